@@ -22,8 +22,11 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
@@ -47,7 +50,23 @@ import (
 
 // main 是后端 HTTP 服务的程序入口（仅 HTTP API，不含 SMTP）。
 func main() {
-	cfg, err := config.Load()
+	cli, err := config.ParseCommandLine("api", os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	// config validate / config print 子命令执行后退出
+	if handled, err := cli.RunCommand(os.Stdout); handled {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := cli.Load()
 	if err != nil {
 		panic(fmt.Sprintf("failed to load config: %v", err))
 	}
@@ -108,11 +127,15 @@ func main() {
 	// 设置邮箱服务和用户域名服务的关联（避免循环依赖）
 	mailboxService.SetUserDomainService(userDomainService)
 
+	// 用户域名过期宽限期（邮箱创建和只读冻结共用；提醒由 server 的定时任务发送）
+	service.SetDomainPolicy(service.DomainPolicy{GracePeriod: cfg.Mailbox.DomainGracePeriod})
+
 	// 初始化管理服务（需要转换配置）
 	domainConfig := &domain.Config{
 		AllowedDomains: cfg.Mailbox.AllowedDomains,
 	}
 	adminService := service.NewAdminService(store, domainConfig)
+	adminService.SetMailboxService(mailboxService)
 
 	// 初始化认证服务
 	authService := auth.NewService(store)
@@ -123,15 +146,47 @@ func main() {
 		cfg.JWT.RefreshExpiry,
 	)
 
+	// 签名密钥：配置了旧密钥时，旧密钥签发的令牌在刷新令牌有效期内仍可验证
+	var previousJWTKey *jwtpkg.Key
+	if cfg.JWT.PreviousSecret != "" {
+		previousJWTKey = &jwtpkg.Key{
+			ID:       cfg.JWT.PreviousSecretKeyID,
+			Secret:   cfg.JWT.PreviousSecret,
+			RetireAt: time.Now().Add(cfg.JWT.RefreshExpiry),
+		}
+	}
+	if err := jwtManager.SetKeys(jwtpkg.Key{ID: cfg.JWT.SecretKeyID, Secret: cfg.JWT.Secret}, previousJWTKey); err != nil {
+		log.Fatal("invalid JWT signing keys", zap.Error(err))
+	}
+
+	// 管理员轮换后的密钥保存在系统配置中，覆盖启动配置，并随运行时配置轮询同步到所有实例
+	jwtKeyService := service.NewJWTKeyService(store, jwtManager)
+	if err := jwtKeyService.Load(context.Background()); err != nil {
+		log.Warn("failed to load rotated JWT keys, using configured secret", zap.Error(err))
+	}
+	configService.OnRuntimeRefresh(jwtKeyService.Apply)
+
+	// 组织/团队：令牌附带成员关系声明
+	orgService := service.NewOrgService(store)
+	jwtManager.SetOrgResolver(orgService.TokenOrgs)
+
+	// 分发列表：发往列表地址的邮件复制到每个成员邮箱，删除邮箱时自动移出列表
+	listService := service.NewDistributionListService(store, messageService, cfg)
+	listService.SetAliasRepository(store)
+	mailboxService.SetMemberPruner(listService)
+
 	log.Info("JWT configuration",
 		zap.String("issuer", cfg.JWT.Issuer),
 		zap.Duration("access_expiry", cfg.JWT.AccessExpiry),
 		zap.Duration("refresh_expiry", cfg.JWT.RefreshExpiry),
+		zap.String("key_id", jwtKeyService.Status().CurrentKeyID),
 	)
 
 	// 创建 WebSocket Hub
-	// 使用 CORS 配置的允许来源列表、JWT密钥和邮箱存储
-	wsHub := websocket.NewHub(cfg.CORS.AllowedOrigins, cfg.JWT.Secret, store)
+	// 使用 CORS 配置的允许来源列表、JWT 管理器（与 HTTP 接口共用签名密钥）和邮箱存储
+	wsHub := websocket.NewHub(cfg.CORS.AllowedOrigins, jwtManager, store)
+	mailboxService.SetDeletionNotifier(wsHub)
+	wsHub.SetNewMailBus(store) // 接收其他实例（SMTP 收信）经 Redis 发布的新邮件
 
 	// 创建 HTTP 路由
 	router := httptransport.NewRouter(httptransport.RouterDependencies{
@@ -145,6 +200,12 @@ func main() {
 		SystemDomainService: systemDomainService, // 添加系统域名服务
 		APIKeyService:       apiKeyService,       // 添加API Key服务
 		ConfigService:       configService,       // 添加系统配置服务
+		StatsService:        service.NewStatsService(store),
+		OrgService:          orgService,
+		DistributionLists:   listService,
+		RedactionService:    service.NewRedactionService(messageService, store),
+		SinkService:         service.NewSinkService(store),
+		JWTKeyService:       jwtKeyService,
 		JWTManager:          jwtManager,
 		WebSocketHub:        wsHub,
 		Store:               store,
@@ -172,6 +233,9 @@ func main() {
 		wsHub.Run(ctx)
 	}()
 
+	// 启动运行时配置同步（维护模式等开关）
+	go configService.WatchRuntimeConfig(ctx, 5*time.Second)
+
 	// 启动 HTTP 服务器
 	go func() {
 		log.Info("API server listening", zap.String("address", addr))
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"tempmail/backend/internal/storage/filesystem"
+)
+
+// main 将旧版按邮件保存的附件迁移为内容寻址 blob，并输出节省的空间。
+func main() {
+	path := flag.String("path", "./data/mail-storage", "文件系统存储根目录")
+	flag.Parse()
+
+	store, err := filesystem.NewStore(*path)
+	if err != nil {
+		fmt.Printf("错误: 无法打开存储目录: %v\n", err)
+		os.Exit(1)
+	}
+
+	before, _ := store.GetStorageStats()
+
+	report, err := store.CompactAttachments()
+	if err != nil {
+		fmt.Printf("错误: 附件迁移失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ 扫描附件 %d 个，迁移 %d 个，新建 blob %d 个\n",
+		report.FilesScanned, report.FilesMigrated, report.BlobsCreated)
+	fmt.Printf("✓ 迁移前 %d 字节，迁移后 %d 字节，节省 %d 字节\n",
+		report.BytesBefore, report.BytesAfter, report.BytesSaved)
+
+	if after, err := store.GetStorageStats(); err == nil && before != nil {
+		fmt.Printf("✓ 磁盘占用: %v -> %v 字节（逻辑大小 %v 字节）\n",
+			before["physical_size_bytes"], after["physical_size_bytes"], after["logical_size_bytes"])
+	}
+}
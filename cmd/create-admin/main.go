@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -77,7 +78,7 @@ func main() {
 		UpdatedAt:       time.Now(),
 	}
 
-	if err := store.CreateUser(user); err != nil {
+	if err := store.CreateUser(context.Background(), user); err != nil {
 		fmt.Printf("Failed to create user: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/storage/datamigrate"
+	"tempmail/backend/internal/storage/memory"
+	"tempmail/backend/internal/storage/postgres"
+)
+
+func main() {
+	// 解析命令行参数（数据库默认取自配置 TEMPMAIL_DATABASE_TYPE / TEMPMAIL_DATABASE_DSN）
+	snapshotPath := flag.String("snapshot", "", "快照文件路径（storage.snapshot_path 写入的文件）")
+	sourceURL := flag.String("source", "", "运行中实例的地址，如 http://localhost:8080（从 /v1/admin/backup/snapshot 导出）")
+	token := flag.String("token", "", "超级管理员的访问令牌（配合 -source 使用）")
+	dbType := flag.String("type", "", "目标数据库类型: mysql、postgres 或 sqlite（默认取自配置）")
+	dbDSN := flag.String("dsn", "", "目标数据库连接字符串（默认取自配置）")
+	dryRun := flag.Bool("dry-run", false, "只统计将要写入的记录，不修改目标数据库")
+	flag.Parse()
+
+	if (*snapshotPath == "") == (*sourceURL == "") {
+		fmt.Println("用法:")
+		fmt.Println("  go run cmd/migrate-data/main.go -snapshot=data/memory-snapshot.json.gz -type=sqlite -dsn='data/tempmail.db' [-dry-run]")
+		fmt.Println("  go run cmd/migrate-data/main.go -source=http://localhost:8080 -token=<jwt> -type=postgres -dsn='postgres://...' [-dry-run]")
+		os.Exit(1)
+	}
+
+	if *dbType == "" || *dbDSN == "" {
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Printf("错误: 加载配置失败: %v\n", err)
+			os.Exit(1)
+		}
+		if *dbType == "" {
+			*dbType = cfg.Database.Type
+		}
+		if *dbDSN == "" {
+			*dbDSN = cfg.Database.DSN
+		}
+	}
+
+	// 读取快照
+	var snap *memory.Snapshot
+	var err error
+	if *snapshotPath != "" {
+		snap, err = memory.ReadSnapshotFile(*snapshotPath)
+	} else {
+		snap, err = fetchSnapshot(*sourceURL, *token)
+	}
+	if err != nil {
+		fmt.Printf("错误: 读取快照失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ 读取快照（%s）: %d 个用户, %d 个邮箱, %d 封邮件\n",
+		snap.CreatedAt.Format(time.RFC3339), len(snap.Users), len(snap.Mailboxes), len(snap.Messages))
+
+	// 连接目标数据库
+	target, err := openTarget(*dbType, *dbDSN)
+	if err != nil {
+		fmt.Printf("错误: 无法连接数据库: %v\n", err)
+		os.Exit(1)
+	}
+	defer target.Close()
+	fmt.Printf("✓ 成功连接到 %s 数据库\n\n", *dbType)
+
+	report := datamigrate.Migrate(context.Background(), snap, target, datamigrate.Options{DryRun: *dryRun})
+	report.Print(os.Stdout)
+
+	if report.HasFailures() {
+		fmt.Printf("\n错误: 部分记录写入失败，修复后可重新执行（已写入的记录会跳过）\n")
+		os.Exit(1)
+	}
+	fmt.Printf("\n✓ 迁移完成\n")
+}
+
+// openTarget 按类型打开目标存储（sqlite 会自动建表，mysql/postgres 需先运行 cmd/migrate）
+func openTarget(dbType, dsn string) (*postgres.Store, error) {
+	switch dbType {
+	case "postgres":
+		return postgres.NewStore(dsn)
+	case "mysql":
+		return postgres.NewMySQLStore(dsn)
+	case "sqlite":
+		return postgres.NewSQLiteStore(dsn)
+	default:
+		return nil, fmt.Errorf("不支持的数据库类型 '%s'", dbType)
+	}
+}
+
+// fetchSnapshot 从运行中实例的管理接口导出快照
+func fetchSnapshot(sourceURL, token string) (*memory.Snapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	url := strings.TrimRight(sourceURL, "/") + "/v1/admin/backup/snapshot"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s 返回 %s", url, resp.Status)
+	}
+	return memory.ReadSnapshot(resp.Body)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/filesystem"
+	"tempmail/backend/internal/storage/postgres"
+)
+
+// batchSize 每批读取和写入索引的邮件数量
+const batchSize = 500
+
+// main 清空并重建邮件全文索引（正文从文件系统存储加载）。
+func main() {
+	// 数据库默认取自配置 TEMPMAIL_DATABASE_TYPE / TEMPMAIL_DATABASE_DSN
+	dbType := flag.String("type", "", "数据库类型: mysql、postgres 或 sqlite（默认取自配置）")
+	dbDSN := flag.String("dsn", "", "数据库连接字符串（默认取自配置）")
+	path := flag.String("path", "", "文件系统存储根目录（默认取自配置，未配置时为 ./data/mail-storage）")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("错误: 加载配置失败: %v\n", err)
+		os.Exit(1)
+	}
+	if *dbType == "" {
+		*dbType = cfg.Database.Type
+	}
+	if *dbDSN == "" {
+		*dbDSN = cfg.Database.DSN
+	}
+	if *path == "" {
+		*path = cfg.Storage.Path
+	}
+	if *path == "" {
+		*path = "./data/mail-storage"
+	}
+
+	store, err := openStore(*dbType, *dbDSN)
+	if err != nil {
+		fmt.Printf("错误: 无法连接数据库: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	fsStore, err := filesystem.NewStore(*path)
+	if err != nil {
+		fmt.Printf("错误: 无法打开存储目录: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	if err := store.ClearSearchIndex(ctx); err != nil {
+		fmt.Printf("错误: 清空索引失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	indexed, missing := 0, 0
+	afterID := ""
+	for {
+		messages, err := store.ListMessagesAfter(ctx, afterID, batchSize)
+		if err != nil {
+			fmt.Printf("错误: 读取邮件失败: %v\n", err)
+			os.Exit(1)
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		docs := make([]*domain.SearchDocument, 0, len(messages))
+		for i := range messages {
+			msg := &messages[i]
+			if msg.HasText || msg.HasHTML {
+				if metadata, err := fsStore.GetMessageMetadata(msg.MailboxID, msg.ID); err == nil {
+					msg.Text, msg.HTML = metadata.Text, metadata.HTML
+				} else {
+					missing++ // 正文文件丢失时仍按主题和发件人索引
+				}
+			}
+			docs = append(docs, domain.NewSearchDocument(msg))
+		}
+		if err := store.IndexMessages(ctx, docs); err != nil {
+			fmt.Printf("错误: 写入索引失败: %v\n", err)
+			os.Exit(1)
+		}
+
+		indexed += len(docs)
+		afterID = messages[len(messages)-1].ID
+		fmt.Printf("  已索引 %d 封邮件\n", indexed)
+	}
+
+	fmt.Printf("✓ 重建完成: 索引 %d 封邮件（%d 封正文缺失）\n", indexed, missing)
+}
+
+// openStore 按类型打开数据库存储（mysql/postgres 需先运行 cmd/migrate 创建索引表）
+func openStore(dbType, dsn string) (*postgres.Store, error) {
+	switch dbType {
+	case "postgres", "postgresql":
+		return postgres.NewStore(dsn)
+	case "mysql":
+		return postgres.NewMySQLStore(dsn)
+	case "sqlite", "sqlite3":
+		return postgres.NewSQLiteStore(dsn)
+	default:
+		return nil, fmt.Errorf("不支持的数据库类型 '%s'", dbType)
+	}
+}
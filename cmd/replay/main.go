@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"tempmail/backend/internal/replay"
+)
+
+// main 将一批原始邮件按 SMTP 入库流程回放，输出归一化结果或与基准结果比较。
+//
+//	go run ./cmd/replay internal/replay/testdata/corpus
+//	go run ./cmd/replay -golden internal/replay/testdata/golden internal/replay/testdata/corpus
+//	go run ./cmd/replay -golden internal/replay/testdata/golden -update internal/replay/testdata/corpus
+func main() {
+	golden := flag.String("golden", "", "基准结果目录；设置后与之比较，有差异时以状态 1 退出")
+	update := flag.Bool("update", false, "用本次结果覆盖基准目录（需同时指定 -golden）")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: %s [-golden 目录 [-update]] <.eml 目录 | zip 压缩包>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || (*update && *golden == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	fixtures, err := replay.Load(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: 无法读取邮件: %v\n", err)
+		os.Exit(1)
+	}
+	replayer, err := replay.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: 无法创建回放器: %v\n", err)
+		os.Exit(1)
+	}
+	records, err := replayer.ReplayAll(context.Background(), fixtures)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: 回放失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch {
+	case *update:
+		if err := replay.WriteGolden(*golden, records); err != nil {
+			fmt.Fprintf(os.Stderr, "错误: 写入基准结果失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ 已更新 %d 个基准结果: %s\n", len(records), *golden)
+
+	case *golden != "":
+		mismatches, err := replay.CompareGolden(*golden, records)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "错误: 读取基准结果失败: %v\n", err)
+			os.Exit(1)
+		}
+		for _, m := range mismatches {
+			fmt.Printf("✗ %s\n%s\n", m.File, m.Diff)
+		}
+		if len(mismatches) > 0 {
+			fmt.Printf("%d/%d 封邮件与基准结果不一致（确认是预期变化后用 -update 更新）\n", len(mismatches), len(records))
+			os.Exit(1)
+		}
+		fmt.Printf("✓ %d 封邮件与基准结果一致\n", len(records))
+
+	default:
+		for _, record := range records {
+			data, err := replay.Marshal(record)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+				os.Exit(1)
+			}
+			os.Stdout.Write(data)
+		}
+	}
+}
@@ -2,8 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
+	"net"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
@@ -13,26 +18,59 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
+	"tempmail/backend/internal/analytics"
 	"tempmail/backend/internal/auth"
 	jwtpkg "tempmail/backend/internal/auth/jwt"
 	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/dkim"
 	"tempmail/backend/internal/domain"
 	"tempmail/backend/internal/health"
+	"tempmail/backend/internal/imap"
+	"tempmail/backend/internal/jobs"
+	"tempmail/backend/internal/leader"
 	"tempmail/backend/internal/logger"
+	"tempmail/backend/internal/mailauth"
+	"tempmail/backend/internal/mailflow"
+	"tempmail/backend/internal/middleware"
 	"tempmail/backend/internal/monitoring"
+	"tempmail/backend/internal/pop3"
+	"tempmail/backend/internal/redact"
 	"tempmail/backend/internal/service"
 	"tempmail/backend/internal/smtp"
+	"tempmail/backend/internal/spam"
 	"tempmail/backend/internal/storage"
 	"tempmail/backend/internal/storage/filesystem"
 	"tempmail/backend/internal/storage/hybrid"
+	"tempmail/backend/internal/storage/instrumented"
 	"tempmail/backend/internal/storage/memory"
+	"tempmail/backend/internal/storage/objectstore"
+	storageredis "tempmail/backend/internal/storage/redis"
+	"tempmail/backend/internal/tracing"
+	"tempmail/backend/internal/translate"
+	grpctransport "tempmail/backend/internal/transport/grpc"
 	httptransport "tempmail/backend/internal/transport/http"
 	"tempmail/backend/internal/websocket"
 )
 
 // main 启动同时包含 HTTP API 与 SMTP 的综合服务。
 func main() {
-	cfg, err := config.Load()
+	cli, err := config.ParseCommandLine("server", os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	// config validate / config print 子命令执行后退出
+	if handled, err := cli.RunCommand(os.Stdout); handled {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := cli.Load()
 	if err != nil {
 		panic(fmt.Sprintf("failed to load config: %v", err))
 	}
@@ -63,29 +101,135 @@ func main() {
 		zap.Bool("development", cfg.Log.Development),
 	)
 
+	// 信号处理
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 链路追踪（未启用时为空实现）
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		log.Fatal("failed to initialize tracing", zap.Error(err))
+	}
+	if cfg.Tracing.Enabled {
+		log.Info("tracing enabled",
+			zap.String("protocol", cfg.Tracing.Protocol),
+			zap.String("endpoint", cfg.Tracing.Endpoint),
+			zap.Float64("sample_ratio", cfg.Tracing.SampleRatio),
+		)
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	// 初始化监控系统
+	metrics := monitoring.NewMetrics()
+	// 注意：promauto 已经自动注册了指标，不需要手动调用 RegisterCustomMetrics()
+
+	// 依赖就绪前先监听 HTTP：存活检查可用，就绪检查和业务接口返回 503，避免编排系统反复重启
+	httpAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	gate := health.NewGate(metrics.HTTPHandler())
+	httpServer := &http.Server{
+		Addr:              httpAddr,
+		Handler:           gate,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	}
+
+	// HTTP 服务器 goroutine
+	group.Go(func() error {
+		log.Info("starting HTTP server", zap.String("address", httpAddr))
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("HTTP server error", zap.Error(err))
+			return err
+		}
+		return nil
+	})
+
+	// startupFailed 依赖未能就绪：收到关闭信号时正常退出，超过期限则以非零状态退出
+	startupFailed := func(component string, err error) {
+		if errors.Is(err, context.Canceled) {
+			log.Info("startup aborted by shutdown signal", zap.String("component", component))
+			_ = httpServer.Close()
+			if err := group.Wait(); err != nil {
+				log.Fatal("server error", zap.Error(err))
+			}
+			return
+		}
+		log.Fatal("dependencies not ready before startup deadline",
+			zap.String("component", component),
+			zap.Duration("startup_timeout", cfg.Server.StartupTimeout),
+			zap.Error(err),
+		)
+	}
+	startupRetry := health.RetryPolicy{Deadline: cfg.Server.StartupTimeout}
+
 	// 初始化存储层
 	var store storage.Store
+	var memStore *memory.Store // 使用内存存储时非空（快照）
 
 	// 根据配置选择存储类型
 	if cfg.Database.Type != "" && cfg.Database.DSN != "" {
-		// 使用数据库存储
-		store, err = initializeDatabaseStorage(cfg, log)
+		// 使用数据库存储，连不上时按退避重试直到 StartupTimeout
+		err = health.Retry(groupCtx, log, "database", startupRetry, func(context.Context) error {
+			var err error
+			store, err = initializeDatabaseStorage(cfg, log)
+			if errors.Is(err, hybrid.ErrUnsupportedDatabase) {
+				return health.Permanent(err)
+			}
+			return err
+		})
 		if err != nil {
-			panic(fmt.Sprintf("failed to initialize database storage: %v", err))
+			startupFailed("database", err)
+			return
 		}
 		log.Info("using database storage", zap.String("type", cfg.Database.Type))
 	} else {
 		// 使用内存存储（开发环境）
-		store = memory.NewStore(cfg.Mailbox.DefaultTTL)
+		memStore = memory.NewStore(cfg.Mailbox.DefaultTTL)
+		store = memStore
 		log.Info("using memory storage (development mode)", zap.Duration("ttl", cfg.Mailbox.DefaultTTL))
-	}
 
-	// 初始化监控系统
-	metrics := monitoring.NewMetrics()
-	// 注意：promauto 已经自动注册了指标，不需要手动调用 RegisterCustomMetrics()
+		// 存在快照时恢复上次的内容
+		if cfg.Storage.SnapshotPath != "" {
+			loaded, err := memStore.LoadSnapshot(cfg.Storage.SnapshotPath)
+			if err != nil {
+				panic(fmt.Sprintf("failed to load memory store snapshot: %v", err))
+			}
+			log.Info("memory store snapshot",
+				zap.String("path", cfg.Storage.SnapshotPath),
+				zap.Bool("restored", loaded),
+				zap.Duration("interval", cfg.Storage.SnapshotInterval),
+			)
+		}
+	}
 
-	// 初始化健康检查
-	healthChecker := health.NewHealthChecker(store, log)
+	// 存储调用计时：按方法和后端记入直方图，慢调用供 /v1/admin/debug/slow-queries 排查
+	storeRecorder := instrumented.NewRecorder(nil, cfg.Database.SlowQueryThreshold, instrumented.DefaultSlowCallCapacity)
+	storeBackend := "memory"
+	hybridStore, _ := store.(*hybrid.Store)
+	if hybridStore != nil {
+		hybridStore.SetCacheMetrics(metrics)
+		cacheBackend := "redis-cache"
+		if !cfg.Redis.Enabled {
+			cacheBackend = "local-cache" // 单机模式的进程内缓存
+		}
+		hybridStore.SetCacheObserver(storeRecorder.Observer(cacheBackend, hybrid.CacheOperations()))
+		hybridStore.SetSlowQueryLog(cfg.Database.SlowQueryThreshold, storeRecorder)
+		storeBackend = "hybrid"
+	}
+	store = instrumented.NewStore(store, storeBackend, storeRecorder)
+
+	// 初始化健康检查：就绪检查逐个探测依赖（文件存储和 SMTP 在初始化后注册）
+	healthChecker := health.NewHealthChecker(log)
+	if hybridStore != nil {
+		healthChecker.AddReadinessCheck("database", hybridStore.PingDatabase)
+		if cfg.Redis.Enabled {
+			healthChecker.AddReadinessCheck("redis", hybridStore.PingCache)
+		}
+	} else {
+		healthChecker.AddReadinessCheck("storage", func(context.Context) error { return store.Health() })
+	}
 
 	// 初始化告警系统
 	alertManager := monitoring.NewAlertManager(log)
@@ -95,43 +239,170 @@ func main() {
 
 	log.Info("monitoring system initialized")
 
-	// 初始化文件系统存储（用于邮件内容和附件）
-	fsStorePath := cfg.Storage.Path
-	if fsStorePath == "" {
-		fsStorePath = "./data/mail-storage" // 默认路径
-	}
-	fsStore, err := filesystem.NewStore(fsStorePath)
-	if err != nil {
-		log.Warn("failed to initialize filesystem storage, continuing without it", zap.Error(err))
-		fsStore = nil
+	// 初始化邮件内容存储（正文和附件）：local 为本地目录，s3 为 S3/MinIO 对象存储
+	var contentStore contentBackend
+	var fsStore *filesystem.Store // 仅本地目录模式（SMTP 流式入库和临时文件清理依赖本地磁盘）
+	if cfg.Storage.Type == "s3" {
+		objStore, err := objectstore.NewStore(cfg.Storage.S3)
+		if err != nil {
+			log.Warn("failed to initialize object storage, continuing without it", zap.Error(err))
+		} else {
+			contentStore = objStore
+			log.Info("object storage initialized",
+				zap.String("endpoint", cfg.Storage.S3.Endpoint),
+				zap.String("bucket", cfg.Storage.S3.Bucket))
+		}
 	} else {
-		log.Info("filesystem storage initialized", zap.String("path", fsStorePath))
+		fsStorePath := cfg.Storage.Path
+		if fsStorePath == "" {
+			fsStorePath = "./data/mail-storage" // 默认路径
+		}
+		// 卷尚未挂载好时的权限/IO 错误按退避重试，其他错误或超过期限时不带文件存储继续运行
+		err = health.Retry(groupCtx, log, "filesystem", startupRetry, func(context.Context) error {
+			var err error
+			fsStore, err = filesystem.NewStore(fsStorePath)
+			if err != nil && !isTransientFSError(err) {
+				return health.Permanent(err)
+			}
+			return err
+		})
+		if errors.Is(err, context.Canceled) {
+			startupFailed("filesystem", err)
+			return
+		}
+		if err != nil {
+			log.Warn("failed to initialize filesystem storage, continuing without it", zap.Error(err))
+			fsStore = nil
+		} else {
+			contentStore = fsStore
+			healthChecker.AddReadinessCheck("filesystem", func(context.Context) error { return fsStore.CheckWritable() })
+			log.Info("filesystem storage initialized", zap.String("path", fsStorePath))
+		}
 	}
 
 	// 初始化服务层
 	mailboxService := service.NewMailboxService(store, store, cfg)
 	messageService := service.NewMessageService(store)
-	// 设置文件系统存储
-	if fsStore != nil {
-		messageService.SetFilesystemStore(fsStore)
+	messageService.SetSearchIndex(store) // 入库后写入全文索引
+	// 设置邮件内容存储
+	if contentStore != nil {
+		messageService.SetFilesystemStore(contentStore)
+		mailboxService.SetContentStore(contentStore) // 删除邮箱时清理文件内容
+	}
+	// 翻译服务（可选，未配置时翻译接口返回 501）
+	if translator, err := translate.New(cfg.Translate); err == nil {
+		messageService.SetTranslator(translator)
+		log.Info("translation provider configured", zap.String("provider", translator.Name()))
+	} else if !errors.Is(err, translate.ErrNotConfigured) {
+		log.Warn("failed to initialize translation provider, translation disabled", zap.Error(err))
+	}
+	// HTML 安全渲染：远程图片默认拦截，proxy 模式下改写为经本服务代理加载
+	var imageProxy *service.ImageProxy
+	safeHTML := redact.SafeHTMLOptions{RemoteImages: cfg.Render.RemoteImages}
+	if cfg.Render.RemoteImages == redact.RemoteImagesProxy {
+		imageProxy = service.NewImageProxy(cfg.JWT.Secret, cfg.Render)
+		safeHTML.ProxyURL = imageProxy.URL
+	}
+	messageService.SetSafeHTMLOptions(safeHTML)
+	// 发信中继（可选，未配置时发信接口返回 503，也不提供转发）
+	var forwardingService *service.ForwardingService
+	var mailSender service.MailSender
+	if outbound := cfg.SMTP.Outbound; outbound.RelayAddr != "" {
+		relay := smtp.NewRelay(outbound, cfg.SMTP.Domain)
+		mailSender = relay
+		messageService.SetOutbound(relay, store, service.SendOptions{
+			MaxRecipients:     outbound.MaxRecipients,
+			MailboxDailyLimit: outbound.MailboxDailyLimit,
+			UserDailyLimit:    outbound.UserDailyLimit,
+			AllowGuests:       outbound.AllowGuests,
+			Domain:            cfg.SMTP.Domain,
+		})
+		forwardingService = service.NewForwardingService(store, store, messageService, relay, service.ForwardOptions{
+			AllowGuests: outbound.AllowGuests,
+			Domain:      cfg.SMTP.Domain,
+		})
+		log.Info("outbound relay configured", zap.String("relay", outbound.RelayAddr), zap.String("tls", outbound.TLS))
 	}
 	aliasService := service.NewAliasService(store, store, cfg)
+	aliasService.SetAllowedDomainSource(mailboxService) // 系统配置修改允许域名后即时生效
 	searchService := service.NewSearchService(store)
+	searchService.SetIndex(store) // 跨邮箱全文搜索
 	webhookService := service.NewWebhookService(store)
 	tagService := service.NewTagService(store) // 初始化标签服务
 	userDomainService := service.NewUserDomainService(store, cfg)
 	systemDomainService := service.NewSystemDomainService(store, cfg) // 初始化系统域名服务
 	apiKeyService := service.NewAPIKeyService(store)                  // 初始化API Key服务
 	configService := service.NewConfigService(store)                  // 初始化系统配置服务
+	configService.SetStartupDefaults(cfg.Mailbox)                     // 管理员保存系统配置前以启动配置为准
 
 	// 设置邮箱服务和用户域名服务的关联（避免循环依赖）
 	mailboxService.SetUserDomainService(userDomainService)
+	// 域名转换时通知原所有者
+	systemDomainService.SetWebhookService(webhookService)
+
+	// 用户域名过期宽限期（SMTP 收件、邮箱创建和只读冻结共用）
+	service.SetDomainPolicy(service.DomainPolicy{GracePeriod: cfg.Mailbox.DomainGracePeriod})
+	domainLifecycleService := service.NewDomainLifecycleService(store)
+	domainLifecycleService.SetWebhookService(webhookService)
+
+	// 配置导出/恢复，每项变更写入审计日志
+	backupService := service.NewSettingsBackupService(store)
+	backupService.SetAuditFunc(func(actorID string, item service.RestoreItem) {
+		log.Info("audit: settings restored",
+			zap.String("actor", actorID),
+			zap.String("kind", item.Kind),
+			zap.String("key", item.Key),
+			zap.String("action", string(item.Action)),
+		)
+	})
+
+	// 活跃 SMTP 会话登记表（SMTP 服务和管理端排查接口共用）
+	smtpSessions := smtp.NewSessionRegistry()
+	smtpSessions.SetMetrics(metrics)
+
+	// 个人数据导出与数据保留报告，每次访问写入审计日志
+	dataAudit := func(event, actorID, subjectID string) {
+		log.Info("audit: data access",
+			zap.String("event", event),
+			zap.String("actor", actorID),
+			zap.String("subject", subjectID),
+		)
+	}
+	userDataService := service.NewUserDataService(store)
+	userSpamService := service.NewUserSpamService(store, cfg)
+	userDataService.SetMessageReader(messageService)
+	userDataService.SetAuditFunc(dataAudit)
+	retentionService := service.NewRetentionService(store)
+	retentionService.SetAuditFunc(dataAudit)
+
+	// 收件统计（邮箱/用户域名）
+	statsService := service.NewStatsService(store)
+
+	// 邮件脱敏副本（分享前遮盖验证码、令牌等）
+	redactionService := service.NewRedactionService(messageService, store)
+
+	// 域名黑洞模式（只累计统计，不保存邮件）
+	sinkService := service.NewSinkService(store)
+
+	// 用户域名通配收件（通配模式的域名接收发往不存在地址的邮件）
+	catchAllService := service.NewCatchAllService(store, mailboxService)
+
+	// 用户域名白名单（白名单模式的域名只允许列表中的前缀创建邮箱和收信）
+	domainWhitelist := service.NewDomainWhitelistService(store, store)
+	mailboxService.SetDomainWhitelist(domainWhitelist)
+	reservedPrefixes := service.NewReservedPrefixService(store, store, cfg)
+	mailboxService.SetReservedPrefixes(reservedPrefixes)
+
+	// 闲置邮箱检测（策略在系统配置中调整，默认关闭）
+	mailboxIdleService := service.NewMailboxIdleService(store)
+	mailboxIdleService.SetWebhookService(webhookService)
 
 	// 初始化管理服务（需要转换配置）
 	domainConfig := &domain.Config{
 		AllowedDomains: cfg.Mailbox.AllowedDomains,
 	}
 	adminService := service.NewAdminService(store, domainConfig)
+	adminService.SetMailboxService(mailboxService)
 
 	// 初始化认证服务
 	authService := auth.NewService(store)
@@ -142,10 +413,40 @@ func main() {
 		cfg.JWT.RefreshExpiry,
 	)
 
+	// 签名密钥：配置了旧密钥时，旧密钥签发的令牌在刷新令牌有效期内仍可验证
+	var previousJWTKey *jwtpkg.Key
+	if cfg.JWT.PreviousSecret != "" {
+		previousJWTKey = &jwtpkg.Key{
+			ID:       cfg.JWT.PreviousSecretKeyID,
+			Secret:   cfg.JWT.PreviousSecret,
+			RetireAt: time.Now().Add(cfg.JWT.RefreshExpiry),
+		}
+	}
+	if err := jwtManager.SetKeys(jwtpkg.Key{ID: cfg.JWT.SecretKeyID, Secret: cfg.JWT.Secret}, previousJWTKey); err != nil {
+		log.Fatal("invalid JWT signing keys", zap.Error(err))
+	}
+
+	// 管理员轮换后的密钥保存在系统配置中，覆盖启动配置，并随运行时配置轮询同步到所有实例
+	jwtKeyService := service.NewJWTKeyService(store, jwtManager)
+	if err := jwtKeyService.Load(ctx); err != nil {
+		log.Warn("failed to load rotated JWT keys, using configured secret", zap.Error(err))
+	}
+	configService.OnRuntimeRefresh(jwtKeyService.Apply)
+
+	// 组织/团队：令牌附带成员关系声明
+	orgService := service.NewOrgService(store)
+	jwtManager.SetOrgResolver(orgService.TokenOrgs)
+
+	// 分发列表：发往列表地址的邮件复制到每个成员邮箱，删除邮箱时自动移出列表
+	listService := service.NewDistributionListService(store, messageService, cfg)
+	listService.SetAliasRepository(store)
+	mailboxService.SetMemberPruner(listService)
+
 	log.Info("JWT configuration",
 		zap.String("issuer", cfg.JWT.Issuer),
 		zap.Duration("access_expiry", cfg.JWT.AccessExpiry),
 		zap.Duration("refresh_expiry", cfg.JWT.RefreshExpiry),
+		zap.String("key_id", jwtKeyService.Status().CurrentKeyID),
 	)
 
 	// 初始化系统域名（从配置文件自动导入）
@@ -157,90 +458,414 @@ func main() {
 	}
 
 	// 创建 WebSocket Hub
-	// 使用 CORS 配置的允许来源列表、JWT密钥和邮箱存储
-	wsHub := websocket.NewHub(cfg.CORS.AllowedOrigins, cfg.JWT.Secret, store)
+	// 使用 CORS 配置的允许来源列表、JWT 管理器（与 HTTP 接口共用签名密钥）和邮箱存储
+	wsHub := websocket.NewHub(cfg.CORS.AllowedOrigins, jwtManager, store)
+	mailboxService.SetDeletionNotifier(wsHub)     // 邮箱删除时撤销订阅并通知客户端
+	domainLifecycleService.SetUserNotifier(wsHub) // 域名过期提醒推送给所有者
+	mailboxIdleService.SetUserNotifier(wsHub)     // 闲置邮箱提醒推送给所有者
+	wsHub.SetActivityRecorder(mailboxIdleService) // 订阅和心跳算作邮箱访问
+	mailboxService.SetPublicAccessRevoker(wsHub)  // 取消公开时撤销匿名订阅
+	adminService.SetSessionCloser(wsHub)          // 停用或删除用户时断开其连接
+	mailboxService.SetMailboxSuspender(wsHub)     // 停用邮箱时撤销订阅并断开邮箱令牌连接
+	wsHub.SetNewMailBus(store)                    // 启用 Redis 时新邮件经发布订阅推送到所有实例
+
+	// 读取过慢的客户端：队列满后丢弃事件，超过阈值以 4006 断开，由客户端重连补发
+	wsHub.SetSendPolicy(websocket.SendPolicy{
+		BufferSize: cfg.WebSocket.SendBuffer,
+		MaxDropped: cfg.WebSocket.MaxDroppedEvents,
+		MaxBlocked: cfg.WebSocket.MaxBlocked,
+	})
+	wsHub.SetMetrics(metrics)
+
+	// 先收信后订阅时补发窗口内的新邮件事件（WebSocket 订阅、创建 Webhook 时 backfill=true）
+	wsHub.SetReplayWindow(cfg.Mailbox.EventReplayWindow)
+	webhookService.SetReplayWindow(cfg.Mailbox.EventReplayWindow)
+
+	// Webhook 按目标主机熔断：连续失败计数复用限流计数器（启用 Redis 时集群内共享）
+	webhookService.Breaker().SetMetrics(metrics)
+	webhookService.Breaker().SetCounters(store)
+
+	// 使用情况统计：只保存汇总计数，发件人按每日轮换的盐哈希（匿名模式下不记录）
+	usageAnalytics := analytics.NewCollector(store, cfg.Analytics, log)
+	usageAnalytics.SetConnectionCounter(wsHub)
+
+	// 收信流量看板：SMTP 后端按分钟统计接收数、拒收原因和发件/收件域名
+	mailFlow := mailflow.NewRecorder(store, cfg.MailFlow, log)
+
+	// 新邮件入库和邮箱过期时触发 Webhook；邮箱 Webhook 的数量上限和重试计划来自系统配置
+	webhookService.SetGuestPolicy(configService)
+	messageService.SetMailReceivedNotifier(service.MailReceivedNotifiers{webhookService, usageAnalytics})
+	mailboxService.SetExpiryNotifier(webhookService)
+
+	// 事务性发件箱：新邮件事件与邮件同一事务写入，WebSocket 推送（启用 Redis 时经发布订阅到所有实例）
+	// 和 mail.received Webhook 由分发器投递，进程在提交后崩溃时由重试任务补发
+	outboxDispatcher := service.NewOutboxDispatcher(store, messageService)
+	outboxDispatcher.Register(service.OutboxTargetWebSocket, func(ctx context.Context, message *domain.Message) error {
+		if !message.Quarantined {
+			wsHub.NotifyNewMail(ctx, message.MailboxID, message)
+		}
+		return nil
+	})
+	messageService.SetOutbox(outboxDispatcher)
+
+	// 邮箱创建、通过 API 删除邮件、域名验证成功和配额用尽时触发 Webhook（邮箱创建同时计入使用情况统计）
+	mailboxService.SetCreatedNotifier(service.MailboxCreatedNotifiers{usageAnalytics, webhookService})
+	messageService.SetMessageDeletedNotifier(webhookService)
+	userDomainService.SetWebhookService(webhookService)
+	mailboxService.SetQuotaNotifier(webhookService)
+	messageService.SetQuotaNotifier(webhookService)
+	listService.SetQuotaNotifier(webhookService)
+
+	// 登录防暴力破解：失败计数复用限流计数器，锁定/解锁写入审计日志，新 IP 登录推送提醒
+	loginGuard := auth.NewLoginGuard(store)
+	loginGuard.SetNotifier(wsHub)
+	loginGuard.SetAuditFunc(func(event, userID, actorID string, until time.Time) {
+		log.Info("audit: login lock",
+			zap.String("event", event),
+			zap.String("user", userID),
+			zap.String("actor", actorID),
+			zap.Time("until", until),
+		)
+	})
+	authService.SetLoginGuard(loginGuard)
 
-	// 创建 HTTP 服务器
-	httpAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	// 两步验证：TOTP 密钥加密保存，未单独配置加密密钥时由 JWT 密钥派生
+	twoFactorKey := cfg.TwoFactor.EncryptionKey
+	if twoFactorKey == "" {
+		log.Warn("two_factor.encryption_key not set, deriving from jwt.secret; set it before rotating the JWT secret")
+		twoFactorKey = cfg.JWT.Secret
+	}
+	totpCipher, err := auth.NewSecretCipher(twoFactorKey)
+	if err != nil {
+		log.Fatal("failed to initialize two-factor cipher", zap.Error(err))
+	}
+	authService.SetTwoFactor(totpCipher, cfg.TwoFactor.Issuer)
+
+	// 登录会话：刷新时轮换令牌，注销后访问令牌加入黑名单（hybrid 存储下黑名单在 Redis 中，多实例共享）
+	sessionService := auth.NewSessionService(store, store, jwtManager)
+	jwtManager.SetBlacklist(sessionService) // HTTP、gRPC、WebSocket 认证共用，注销或撤销会话后访问令牌立即失效
+
+	// 自助账户管理：修改密码后注销其他会话，邮箱验证码经发信中继发送（未配置中继时不能验证邮箱）
+	accountService := service.NewAccountService(store, authService, store, service.AccountOptions{Domain: cfg.SMTP.Domain})
+	accountService.SetSessions(sessionService)
+	accountService.SetMailboxService(mailboxService)
+	accountService.SetSessionCloser(wsHub)
+	accountService.SetMailSender(mailSender)
+
+	// 第三方登录（Google、GitHub、自定义 OIDC IdP），未配置任何提供方时不注册路由
+	oauthService := newOAuthService(cfg.OAuth, store, log)
+
+	// 公开收件箱（只读，邮件保留 1 小时）
+	publicInboxService := service.NewPublicInboxService(store, messageService)
+
+	// 邮件到期规则（如验证码邮件 15 分钟后删除），删除后推送邮箱统计变化
+	messageExpiryService := service.NewMessageExpiryService(store, messageService, cfg)
+	messageExpiryService.SetMailboxUpdateNotifier(wsHub)
+	messageExpiryService.SetDefaultTTLSource(mailboxService)
+
+	// 按用户等级的邮件保留时长（在系统配置中设置，默认不限制）
+	messageRetentionService := service.NewMessageRetentionService(store, messageService, configService)
+	messageRetentionService.SetMailboxUpdateNotifier(wsHub)
+
+	// 通过 API 删除邮件后推送最新的邮箱统计
+	messageService.SetMailboxUpdateNotifier(store, wsHub)
+
+	// 滥用举报：处理结果写入审计日志，同一邮箱被多人举报时告警
+	abuseReportService := service.NewAbuseReportService(store, mailboxService)
+	abuseReportService.SetAlerter(alertManager)
+	abuseReportService.SetAuditFunc(func(event, actorID, reportID, targetID string) {
+		log.Info("audit: abuse report",
+			zap.String("event", event),
+			zap.String("actor", actorID),
+			zap.String("report", reportID),
+			zap.String("target", targetID),
+		)
+	})
+
+	// 邮件分享链接：签名密钥由当前 JWT 密钥派生，轮换 JWT 密钥后已有链接失效
+	messageShareService := service.NewMessageShareService(store, messageService, jwtManager)
+	messageShareService.SetRedactionService(redactionService)
+
+	// 邮箱导出（mbox / eml zip），邮件内容与 POP3/IMAP 一致
+	mailboxExport := service.NewMailboxExportService(messageService, cfg.SMTP.Domain)
+
+	// 管理员维护任务（历史邮件回填预览、重新统计邮箱），按游标分批执行，重启后继续
+	maintenanceJobs := jobs.NewRunner(store, log)
+	maintenanceJobs.Register(domain.MaintenanceJobBackfillPreviews, jobs.NewBackfillPreviews(store, messageService))
+	maintenanceJobs.Register(domain.MaintenanceJobRecountMailboxes, jobs.NewRecountMailboxes(store))
+	maintenanceJobs.SetBatchSize(cfg.Jobs.BatchSize)
+	maintenanceJobs.SetConcurrency(cfg.Jobs.Concurrency)
+	maintenanceJobs.SetRateLimit(cfg.Jobs.RateLimit)
+
+	// 公开状态监控（运行时间历史持久化到邮件内容存储）
+	var uptimeStore monitoring.UptimeStore
+	if contentStore != nil {
+		uptimeStore = contentStore
+	}
+	statusMonitor := monitoring.NewStatusMonitor(store, monitoring.NewUptimeRecorder(uptimeStore), log)
+	statusMonitor.SetBacklogSource(webhookService, 0)
+
+	// 存储快照导出（仅内存存储，供 migrate-data 迁移到数据库）
+	var snapshotter httptransport.StoreSnapshotter
+	if memStore != nil {
+		snapshotter = memStore
+	}
+
+	// 创建 SMTP 后端（支持动态域名配置；开发模式发信接口复用同一入库流程）
+	// 文件系统存储不可用（或使用对象存储）时传入 nil 接口，SMTP 回退为内存入库
+	var smtpFS smtp.FilesystemStore
+	if fsStore != nil {
+		smtpFS = fsStore
+	}
+	smtpBackend := smtp.NewBackend(mailboxService, messageService, aliasService, systemDomainService, userDomainService, wsHub, smtpFS)
+	smtpBackend.SetMaintenanceChecker(configService)
+	// 收信时保存的头名单：系统配置未设置时使用启动配置
+	configService.SetDefaultCapturedHeaders(cfg.Mailbox.CapturedHeaders)
+	smtpBackend.SetHeaderAllowlist(configService)
+	if cfg.SMTP.VerifyDKIM {
+		smtpBackend.SetDKIMVerifier(dkim.NewVerifier(nil))
+	}
+	if cfg.SMTP.Authentication.Enabled {
+		smtpBackend.SetAuthenticator(mailauth.NewAuthenticator(nil, cfg.SMTP.Authentication.FailureAction))
+	}
+	smtpBackend.SetIngestRecorder(statusMonitor.Signals())
+	smtpBackend.SetDistributionLists(listService)
+	smtpBackend.SetSinkService(sinkService)
+	smtpBackend.SetCatchAllService(catchAllService)
+	smtpBackend.SetDomainWhitelist(domainWhitelist)
+	smtpBackend.SetForwardingService(forwardingService)
+	smtpBackend.SetMaxRecipients(cfg.SMTP.MaxRecipients)
+	smtpBackend.SetRecipientMetrics(metrics)
+	smtpBackend.SetInboundProtection(configService) // 收信限流和灰名单（系统配置 inbound，运行时可改）
+	smtpBackend.SetRejectionMetrics(metrics)
+	smtpBackend.SetMailFlowRecorder(mailFlow)
+	smtpBackend.SetSessionRegistry(smtpSessions)
+	// 垃圾邮件评分（可选，未配置时不评分）
+	if spamFilter, err := spam.New(cfg.Spam); err == nil {
+		spamFilter.SetMetrics(metrics)
+		smtpBackend.SetSpamFilter(spamFilter)
+		smtpBackend.SetUserLookup(store) // 用户级阈值
+		log.Info("spam scoring enabled", zap.String("provider", spamFilter.Name()), zap.Bool("failOpen", cfg.Spam.FailOpen))
+	} else if !errors.Is(err, spam.ErrNotConfigured) {
+		log.Warn("failed to initialize spam provider, spam scoring disabled", zap.Error(err))
+	}
+	// 附件病毒扫描（可选，检出威胁的邮件投递到隔离区）
+	if scanner, err := service.NewAttachmentScanner(cfg.Scan); err == nil {
+		scanService := service.NewAttachmentScanService(scanner, cfg.Scan)
+		scanService.SetMetrics(metrics)
+		smtpBackend.SetAttachmentScanner(scanService)
+		log.Info("attachment scanning enabled", zap.String("provider", scanService.Name()), zap.String("address", cfg.Scan.Address), zap.Bool("failOpen", cfg.Scan.FailOpen))
+	} else if !errors.Is(err, service.ErrScannerNotConfigured) {
+		log.Warn("failed to initialize attachment scanner, attachment scanning disabled", zap.Error(err))
+	}
+
+	// 开发模式发信接口（仅 log.development 开启时注册）
+	var devMail httptransport.MailInjector
+	if cfg.Log.Development {
+		devMail = smtpBackend
+	}
+
+	// 按用户等级的每分钟请求配额（hybrid 存储下计数在 Redis 中，多实例共享）
+	requestQuota := middleware.NewRequestQuota(store, log)
+
+	// 系统配置 rateLimit 节控制的全局 IP 限流（管理员修改后即时生效）
+	rateLimits := middleware.NewRuntimeRateLimits()
+
+	// 管理操作审计与客服代登录（只读短期令牌）
+	auditService := service.NewAuditService(store)
+	impersonation := service.NewImpersonationService(store, jwtManager)
+
+	// 创建 HTTP 路由
 	router := httptransport.NewRouter(httptransport.RouterDependencies{
-		Config:              cfg,
-		MailboxService:      mailboxService,
-		MessageService:      messageService,
-		AliasService:        aliasService,
-		SearchService:       searchService,  // 添加搜索服务
-		WebhookService:      webhookService, // 添加 Webhook 服务
-		TagService:          tagService,     // 添加标签服务
-		AuthService:         authService,
-		AdminService:        adminService,
-		UserDomainService:   userDomainService,
-		SystemDomainService: systemDomainService, // 添加系统域名服务
-		APIKeyService:       apiKeyService,       // 添加API Key服务
-		ConfigService:       configService,       // 添加系统配置服务
-		JWTManager:          jwtManager,
-		WebSocketHub:        wsHub,
-		Store:               store,
-		Logger:              log,
+		Config:               cfg,
+		MailboxService:       mailboxService,
+		MessageService:       messageService,
+		AliasService:         aliasService,
+		SearchService:        searchService,  // 添加搜索服务
+		WebhookService:       webhookService, // 添加 Webhook 服务
+		TagService:           tagService,     // 添加标签服务
+		AuthService:          authService,
+		OAuthService:         oauthService,
+		SessionService:       sessionService,
+		AccountService:       accountService,
+		AdminService:         adminService,
+		UserDomainService:    userDomainService,
+		SystemDomainService:  systemDomainService,  // 添加系统域名服务
+		APIKeyService:        apiKeyService,        // 添加API Key服务
+		ConfigService:        configService,        // 添加系统配置服务
+		BackupService:        backupService,        // 配置导出/恢复
+		UserDataService:      userDataService,      // 个人数据导出
+		UserSpamService:      userSpamService,      // 用户级垃圾邮件阈值
+		RetentionService:     retentionService,     // 数据保留报告
+		SMTPSessions:         smtpSessions,         // 活跃 SMTP 会话
+		Snapshotter:          snapshotter,          // 内存存储快照导出
+		DevMail:              devMail,              // 开发模式发信
+		StatsService:         statsService,         // 收件统计
+		OrgService:           orgService,           // 组织/团队
+		DistributionLists:    listService,          // 分发列表
+		RedactionService:     redactionService,     // 邮件脱敏副本
+		SinkService:          sinkService,          // 域名黑洞模式
+		CatchAllService:      catchAllService,      // 用户域名通配收件
+		DomainWhitelist:      domainWhitelist,      // 用户域名白名单
+		ReservedPrefixes:     reservedPrefixes,     // 系统域名保留前缀
+		ImageProxy:           imageProxy,           // 邮件远程图片代理
+		MailboxIdleService:   mailboxIdleService,   // 闲置邮箱检测
+		PublicInboxService:   publicInboxService,   // 公开收件箱
+		MessageExpiryService: messageExpiryService, // 邮件到期规则
+		AbuseReportService:   abuseReportService,   // 滥用举报
+		MessageShareService:  messageShareService,  // 邮件分享链接
+		MailboxExport:        mailboxExport,        // 邮箱导出
+		ForwardingService:    forwardingService,    // 邮箱转发
+		MaintenanceJobs:      maintenanceJobs,      // 维护任务
+		Analytics:            usageAnalytics,       // 使用情况统计
+		MailFlow:             mailFlow,             // 收信流量看板
+		StatusMonitor:        statusMonitor,        // 公开状态页
+		StoreRecorder:        storeRecorder,        // 慢调用排查
+		RequestQuota:         requestQuota,
+		RateLimits:           rateLimits,
+		AuditService:         auditService,
+		Impersonation:        impersonation,
+		JWTKeyService:        jwtKeyService,
+		JWTManager:           jwtManager,
+		WebSocketHub:         wsHub,
+		Store:                store,
+		Logger:               log,
 	})
 
 	// 添加额外的健康检查和监控端点
 	// 注意：/health 端点已在 router.go 中注册
 
-	// 健康检查处理器（用于 Kubernetes 等）
-	router.GET("/health/live", gin.WrapH(healthChecker.Handler()))
-	router.GET("/health/ready", gin.WrapH(healthChecker.Handler()))
+	// 健康检查处理器（用于 Kubernetes 等）：存活检查不探测依赖，就绪检查返回每个依赖的状态
+	healthChecker.AddReadinessCheck("smtp", health.SMTPCheck(cfg.SMTP.BindAddr))
+	router.GET("/health/live", gin.WrapH(healthChecker.LivenessHandler()))
+	router.GET("/health/ready", gin.WrapH(healthChecker.ReadinessHandler()))
 
 	// Prometheus 指标端点
 	router.GET("/metrics", gin.WrapH(metrics.HTTPHandler()))
 
-	httpServer := &http.Server{
-		Addr:              httpAddr,
-		Handler:           router,
-		ReadHeaderTimeout: 5 * time.Second,
-		ReadTimeout:       30 * time.Second,
-		WriteTimeout:      30 * time.Second,
-		IdleTimeout:       120 * time.Second,
-	}
-
-	// 创建 SMTP 服务器（支持动态域名配置）
-	smtpBackend := smtp.NewBackend(mailboxService, messageService, aliasService, systemDomainService, userDomainService, wsHub, fsStore)
+	// 创建 SMTP 服务器
 	smtpServer := gosmtp.NewServer(smtpBackend)
 	smtpServer.Addr = cfg.SMTP.BindAddr
 	smtpServer.Domain = cfg.SMTP.Domain
 	smtpServer.AllowInsecureAuth = cfg.Log.Development // 仅在开发模式允许不安全认证
 	smtpServer.ReadTimeout = 10 * time.Second
 	smtpServer.WriteTimeout = 10 * time.Second
-	smtpServer.MaxMessageBytes = 10 * 1024 * 1024 // 10MB
-	smtpServer.MaxRecipients = 50
-
-	// 信号处理
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
-
-	group, groupCtx := errgroup.WithContext(ctx)
-
-	// HTTP 服务器 goroutine
-	group.Go(func() error {
-		log.Info("starting HTTP server", zap.String("address", httpAddr))
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Error("HTTP server error", zap.Error(err))
-			return err
+	// 协议层上限仅作兜底：邮件大小上限（系统配置 smtp.maxSize，运行时可改）和
+	// 单事务收件人上限由 Backend 控制（超出时分别返回 552、452）
+	smtpServer.MaxMessageBytes = domain.MaxSMTPMessageSize
+	smtpServer.MaxRecipients = max(50, cfg.SMTP.MaxRecipients)
+
+	// 系统配置修改后即时生效：允许域名、默认有效期、邮件大小上限和全局限流。
+	// 本实例保存时立即通知，其他实例在下一次轮询（WatchRuntimeConfig）时生效
+	configService.OnRuntimeRefresh(mailboxService.ApplySystemConfig)
+	configService.OnRuntimeRefresh(rateLimits.ApplySystemConfig)
+	configService.OnRuntimeRefresh(func(config *domain.SystemConfig) {
+		smtpBackend.SetMaxMessageBytes(config.SMTP.MaxSize)
+		if memStore != nil {
+			if ttl, err := time.ParseDuration(config.Mailbox.DefaultTTL); err == nil && ttl >= 0 {
+				memStore.SetDefaultTTL(ttl)
+			}
 		}
-		return nil
 	})
+	if err := configService.RefreshRuntimeConfig(ctx); err != nil {
+		log.Warn("failed to load system config, using startup config", zap.Error(err))
+	}
+
+	// 依赖已就绪，完整路由接管 HTTP 请求
+	gate.Open(router)
+	log.Info("dependencies ready, serving API")
 
-	// SMTP 服务器 goroutine
+	// SMTP 会话上下文在排空结束后才取消，关闭期间进行中的入库调用可以完成
+	smtpSessionCtx, cancelSMTPSessions := context.WithCancel(context.Background())
+	defer cancelSMTPSessions()
+	smtpBackend.SetBaseContext(smtpSessionCtx)
+
+	// SMTP 服务器：存储确认可用后才监听，之前发件方 MTA 连接失败会自行排队重试。
+	// 监听器由这里持有，关闭时先停止接受新连接，排空后再断开剩余连接。
+	smtpListener, err := net.Listen("tcp", cfg.SMTP.BindAddr)
+	if err != nil {
+		log.Fatal("failed to listen on SMTP address", zap.String("address", cfg.SMTP.BindAddr), zap.Error(err))
+	}
 	group.Go(func() error {
 		log.Info("starting SMTP server",
 			zap.String("address", cfg.SMTP.BindAddr),
 			zap.String("domain", cfg.SMTP.Domain),
 		)
-		if err := smtpServer.ListenAndServe(); err != nil {
+		statusMonitor.Signals().SetSMTPListening(true)
+		defer statusMonitor.Signals().SetSMTPListening(false)
+		if err := smtpServer.Serve(smtpListener); err != nil && !errors.Is(err, net.ErrClosed) {
 			log.Error("SMTP server error", zap.Error(err))
 			return err
 		}
 		return nil
 	})
 
+	// POP3 服务器（可选）：邮箱地址作为用户名、邮箱令牌作为密码，供自动化工具和旧客户端轮询收件箱
+	var pop3Server *pop3.Server
+	if cfg.POP3.Enabled {
+		pop3Server = pop3.NewServer(mailboxService, messageService, log.Named("pop3"))
+		pop3Server.Addr = cfg.POP3.BindAddr
+		pop3Server.Domain = cfg.SMTP.Domain
+		pop3Server.IdleTimeout = cfg.POP3.IdleTimeout
+		pop3Server.SetBaseContext(groupCtx)
+		pop3Server.SetMaintenanceChecker(configService) // 维护只读模式下不删除邮件
+
+		group.Go(func() error {
+			log.Info("starting POP3 server", zap.String("address", cfg.POP3.BindAddr))
+			if err := pop3Server.ListenAndServe(); err != nil && !errors.Is(err, pop3.ErrServerClosed) {
+				log.Error("POP3 server error", zap.Error(err))
+				return err
+			}
+			return nil
+		})
+	}
+
+	// IMAP 服务器（可选）：以单一 INBOX 提供给桌面邮件客户端，密码为邮箱令牌或所属用户的应用专用密码
+	var imapServer *imap.Server
+	if cfg.IMAP.Enabled {
+		imapServer = imap.NewServer(store, messageService, log.Named("imap"))
+		imapServer.SetAppPasswords(auth.NewAppPasswordVerifier(store, jwtManager))
+		imapServer.Addr = cfg.IMAP.BindAddr
+		imapServer.Domain = cfg.SMTP.Domain
+		imapServer.IdleTimeout = cfg.IMAP.IdleTimeout
+		imapServer.SetBaseContext(groupCtx)
+		imapServer.SetMaintenanceChecker(configService) // 维护只读模式下拒绝 STORE、EXPUNGE
+
+		group.Go(func() error {
+			log.Info("starting IMAP server", zap.String("address", cfg.IMAP.BindAddr))
+			if err := imapServer.ListenAndServe(); err != nil && !errors.Is(err, imap.ErrServerClosed) {
+				log.Error("IMAP server error", zap.Error(err))
+				return err
+			}
+			return nil
+		})
+	}
+
+	// gRPC API（可选）：面向高并发测试工具，新邮件以服务端流推送
+	var grpcServer *grpctransport.Server
+	if cfg.GRPC.Enabled {
+		grpcServer = grpctransport.NewServer(mailboxService, messageService, log.Named("grpc"))
+		grpcServer.Addr = cfg.GRPC.BindAddr
+		grpcServer.SetUserAccess(jwtManager, service.NewAuthorizer(store))
+		grpcServer.SetEventHub(wsHub)
+		grpcServer.SetActivityRecorder(mailboxIdleService)
+		grpcServer.SetMaintenanceChecker(configService)
+		grpcServer.SetRequestQuota(requestQuota)
+
+		group.Go(func() error {
+			log.Info("starting gRPC server", zap.String("address", cfg.GRPC.BindAddr))
+			if err := grpcServer.ListenAndServe(); err != nil && !errors.Is(err, grpctransport.ErrServerClosed) {
+				log.Error("gRPC server error", zap.Error(err))
+				return err
+			}
+			return nil
+		})
+	}
+
+	// 定时任务协调：多实例共享 Redis 时每个周期只有一个实例执行清理和重试任务
+	tasks, closeTaskLocks := newTaskCoordinator(cfg, hybridStore != nil, log)
+	defer closeTaskLocks()
+
 	// 定时清理过期邮箱 goroutine
 	group.Go(func() error {
 		ticker := time.NewTicker(1 * time.Hour) // 每小时执行一次
@@ -254,11 +879,63 @@ func main() {
 				log.Info("cleanup task stopped")
 				return nil
 			case <-ticker.C:
-				count, err := store.DeleteExpiredMailboxes()
-				if err != nil {
-					log.Error("failed to cleanup expired mailboxes", zap.Error(err))
-				} else if count > 0 {
-					log.Info("expired mailboxes cleaned up", zap.Int("count", count))
+				// 各步骤的错误单独记录，这里只记录获取锁失败和锁丢失
+				ran, err := tasks.Run(groupCtx, "expired_mailbox_cleanup", time.Hour, func(ctx context.Context) error {
+					var errs []error
+
+					// 先续期即将过期的自动续期邮箱，再删除过期邮箱
+					renewed, err := mailboxService.RenewExpiring(ctx)
+					if err != nil {
+						log.Error("failed to renew auto-renew mailboxes", zap.Error(err))
+						errs = append(errs, err)
+					}
+					if renewed > 0 {
+						log.Info("auto-renew mailboxes extended", zap.Int("count", renewed))
+					}
+
+					// 逐个发送 mailbox.expired 后删除（MongoDB 的 TTL 索引在宽限期后才删除，只兜底本任务未运行的情况）
+					count, err := mailboxService.DeleteExpired(ctx)
+					if err != nil {
+						log.Error("failed to cleanup expired mailboxes", zap.Error(err))
+						errs = append(errs, err)
+					}
+					if count > 0 {
+						log.Info("expired mailboxes cleaned up", zap.Int("count", count))
+					}
+
+					// 删除刷新令牌已过期的登录会话
+					sessions, err := sessionService.CleanupExpired(ctx)
+					if err != nil {
+						log.Error("failed to cleanup expired sessions", zap.Error(err))
+						errs = append(errs, err)
+					}
+					if sessions > 0 {
+						log.Info("expired sessions cleaned up", zap.Int64("count", sessions))
+					}
+
+					// 对账：重试失败的文件/缓存清理，删除没有对应邮箱的孤儿内容
+					orphans, err := mailboxService.ReconcileOrphans(ctx)
+					if err != nil {
+						log.Warn("mailbox orphan reconciliation incomplete", zap.Error(err))
+						errs = append(errs, err)
+					}
+					if orphans > 0 {
+						log.Info("orphaned mailbox artifacts cleaned up", zap.Int("count", orphans))
+					}
+
+					// 清理进程异常退出残留的入库临时文件
+					if fsStore != nil {
+						if removed, err := fsStore.CleanupIngestTemp(time.Hour); err != nil {
+							log.Warn("failed to cleanup ingest temp files", zap.Error(err))
+							errs = append(errs, err)
+						} else if removed > 0 {
+							log.Info("stale ingest temp files removed", zap.Int("count", removed))
+						}
+					}
+					return errors.Join(errs...)
+				})
+				if err != nil && (!ran || errors.Is(err, leader.ErrLockLost)) {
+					log.Error("expired mailbox cleanup run failed", zap.Error(err))
 				}
 			}
 		}
@@ -277,11 +954,148 @@ func main() {
 				log.Info("unverified domains cleanup task stopped")
 				return nil
 			case <-ticker.C:
-				count, err := systemDomainService.CleanupUnverifiedDomains()
-				if err != nil {
+				if _, err := tasks.Run(groupCtx, "unverified_domain_cleanup", time.Hour, func(ctx context.Context) error {
+					count, err := systemDomainService.CleanupUnverifiedDomains(ctx)
+					if count > 0 {
+						log.Info("unverified system domains cleaned up", zap.Int("count", count))
+					}
+					return err
+				}); err != nil {
 					log.Error("failed to cleanup unverified system domains", zap.Error(err))
-				} else if count > 0 {
-					log.Info("unverified system domains cleaned up", zap.Int("count", count))
+				}
+			}
+		}
+	})
+
+	// 定时检查用户域名过期并发送宽限期提醒 goroutine
+	group.Go(func() error {
+		ticker := time.NewTicker(1 * time.Hour) // 每小时执行一次
+		defer ticker.Stop()
+
+		log.Info("starting domain expiry check task",
+			zap.Duration("interval", 1*time.Hour),
+			zap.Duration("grace_period", cfg.Mailbox.DomainGracePeriod))
+
+		for {
+			select {
+			case <-groupCtx.Done():
+				log.Info("domain expiry check task stopped")
+				return nil
+			case <-ticker.C:
+				if _, err := tasks.Run(groupCtx, "domain_expiry_check", time.Hour, func(ctx context.Context) error {
+					count, err := domainLifecycleService.CheckExpirations(ctx)
+					if count > 0 {
+						log.Info("domain expiry notices sent", zap.Int("count", count))
+					}
+					return err
+				}); err != nil {
+					log.Error("failed to check user domain expirations", zap.Error(err))
+				}
+			}
+		}
+	})
+
+	// 定时检查闲置邮箱并提醒所有者 goroutine
+	group.Go(func() error {
+		ticker := time.NewTicker(1 * time.Hour) // 每小时执行一次
+		defer ticker.Stop()
+
+		log.Info("starting idle mailbox check task", zap.Duration("interval", 1*time.Hour))
+
+		for {
+			select {
+			case <-groupCtx.Done():
+				log.Info("idle mailbox check task stopped")
+				return nil
+			case <-ticker.C:
+				if _, err := tasks.Run(groupCtx, "idle_mailbox_check", time.Hour, func(ctx context.Context) error {
+					count, err := mailboxIdleService.CheckIdle(ctx)
+					if count > 0 {
+						log.Info("idle mailboxes flagged", zap.Int("count", count))
+					}
+					return err
+				}); err != nil {
+					log.Error("failed to check idle mailboxes", zap.Error(err))
+				}
+			}
+		}
+	})
+
+	// 定时清理公开收件箱中超过保留时长的邮件 goroutine
+	group.Go(func() error {
+		ticker := time.NewTicker(1 * time.Minute) // 每分钟执行一次
+		defer ticker.Stop()
+
+		log.Info("starting public inbox retention task",
+			zap.Duration("interval", 1*time.Minute),
+			zap.Duration("retention", service.PublicInboxRetention))
+
+		for {
+			select {
+			case <-groupCtx.Done():
+				log.Info("public inbox retention task stopped")
+				return nil
+			case <-ticker.C:
+				if _, err := tasks.Run(groupCtx, "public_inbox_retention", time.Minute, func(ctx context.Context) error {
+					count, err := publicInboxService.SweepExpired()
+					if count > 0 {
+						log.Info("public inbox messages expired", zap.Int("count", count))
+					}
+					return err
+				}); err != nil {
+					log.Error("failed to sweep public inboxes", zap.Error(err))
+				}
+			}
+		}
+	})
+
+	// 定时删除按到期规则过期的邮件 goroutine
+	group.Go(func() error {
+		ticker := time.NewTicker(1 * time.Minute) // 每分钟执行一次（规则最短 1 分钟）
+		defer ticker.Stop()
+
+		log.Info("starting message expiry rule task", zap.Duration("interval", 1*time.Minute))
+
+		for {
+			select {
+			case <-groupCtx.Done():
+				log.Info("message expiry rule task stopped")
+				return nil
+			case <-ticker.C:
+				if _, err := tasks.Run(groupCtx, "message_expiry_rules", time.Minute, func(ctx context.Context) error {
+					count, err := messageExpiryService.SweepExpired(ctx)
+					if count > 0 {
+						log.Info("messages expired by mailbox rules", zap.Int("count", count))
+					}
+					return err
+				}); err != nil {
+					log.Error("failed to delete expired messages", zap.Error(err))
+				}
+			}
+		}
+	})
+
+	// 定时删除超过用户等级保留时长的邮件 goroutine
+	group.Go(func() error {
+		ticker := time.NewTicker(1 * time.Minute) // 每分钟执行一次（保留时长最短 1 分钟）
+		defer ticker.Stop()
+
+		log.Info("starting tier retention task", zap.Duration("interval", 1*time.Minute))
+
+		for {
+			select {
+			case <-groupCtx.Done():
+				log.Info("tier retention task stopped")
+				return nil
+			case <-ticker.C:
+				if _, err := tasks.Run(groupCtx, "tier_retention", time.Minute, func(ctx context.Context) error {
+					count, err := messageRetentionService.SweepExpired(ctx)
+					if count > 0 {
+						log.Info("messages expired by tier retention", zap.Int("count", count))
+					}
+					return err
+				}); err != nil {
+					log.Error("failed to delete messages past tier retention", zap.Error(err))
 				}
 			}
 		}
@@ -300,20 +1114,136 @@ func main() {
 				log.Info("webhook retry task stopped")
 				return nil
 			case <-ticker.C:
-				if err := webhookService.RetryFailedDeliveries(); err != nil {
+				if _, err := tasks.Run(groupCtx, "webhook_retry", 5*time.Minute, func(ctx context.Context) error {
+					return webhookService.RetryFailedDeliveries(ctx)
+				}); err != nil {
 					log.Error("failed to retry webhook deliveries", zap.Error(err))
 				}
 			}
 		}
 	})
 
+	// 发件箱分发 goroutine：补发写入方未投递完的事件（按租约认领，各实例都参与），
+	// 按小时清理处理完成超过一天的事件（只由一个实例执行）
+	group.Go(func() error {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		cleanup := time.NewTicker(time.Hour)
+		defer cleanup.Stop()
+
+		log.Info("starting outbox dispatch task", zap.Duration("interval", 10*time.Second))
+
+		for {
+			select {
+			case <-groupCtx.Done():
+				log.Info("outbox dispatch task stopped")
+				return nil
+			case <-ticker.C:
+				if _, err := outboxDispatcher.RetryPending(groupCtx); err != nil {
+					log.Error("failed to dispatch outbox events", zap.Error(err))
+				}
+			case <-cleanup.C:
+				if _, err := tasks.Run(groupCtx, "outbox_cleanup", time.Hour, func(ctx context.Context) error {
+					deleted, err := outboxDispatcher.Cleanup(ctx, 24*time.Hour)
+					if deleted > 0 {
+						log.Info("cleaned up processed outbox events", zap.Int64("count", deleted))
+					}
+					return err
+				}); err != nil {
+					log.Error("failed to clean up outbox events", zap.Error(err))
+				}
+			}
+		}
+	})
+
+	// 定时重试失败的邮件转发 goroutine
+	if forwardingService != nil {
+		group.Go(func() error {
+			ticker := time.NewTicker(1 * time.Minute) // 每分钟执行一次（最短重试间隔 1 分钟）
+			defer ticker.Stop()
+
+			log.Info("starting forward retry task", zap.Duration("interval", 1*time.Minute))
+
+			for {
+				select {
+				case <-groupCtx.Done():
+					log.Info("forward retry task stopped")
+					return nil
+				case <-ticker.C:
+					if _, err := tasks.Run(groupCtx, "forward_retry", time.Minute, forwardingService.RetryPending); err != nil {
+						log.Error("failed to retry forward deliveries", zap.Error(err))
+					}
+				}
+			}
+		})
+	}
+
+	// 内存存储定期快照 goroutine（关闭时的最后一次快照在所有服务停止后写入）
+	if memStore != nil && cfg.Storage.SnapshotPath != "" {
+		group.Go(func() error {
+			ticker := time.NewTicker(cfg.Storage.SnapshotInterval)
+			defer ticker.Stop()
+
+			log.Info("starting memory store snapshot task", zap.Duration("interval", cfg.Storage.SnapshotInterval))
+
+			for {
+				select {
+				case <-groupCtx.Done():
+					log.Info("memory store snapshot task stopped")
+					return nil
+				case <-ticker.C:
+					if err := memStore.SaveSnapshot(cfg.Storage.SnapshotPath); err != nil {
+						log.Error("failed to write memory store snapshot", zap.Error(err))
+					}
+				}
+			}
+		})
+	}
+
+	// 运行时配置同步 goroutine（维护模式等开关在多实例间传播）
+	group.Go(func() error {
+		log.Info("starting runtime config watcher", zap.Duration("interval", 5*time.Second))
+		configService.WatchRuntimeConfig(groupCtx, 5*time.Second)
+		return nil
+	})
+
 	// WebSocket Hub goroutine
 	group.Go(func() error {
 		log.Info("starting WebSocket hub")
+		statusMonitor.Signals().SetWebSocketRunning(true)
+		defer statusMonitor.Signals().SetWebSocketRunning(false)
 		wsHub.Run(groupCtx)
 		return nil
 	})
 
+	// 公开状态采样 goroutine（每分钟一次，每 10 次持久化）
+	group.Go(func() error {
+		log.Info("starting status monitor", zap.Duration("interval", time.Minute))
+		statusMonitor.Run(groupCtx, time.Minute, 10)
+		return nil
+	})
+
+	// 维护任务调度 goroutine（启动时继续上次未完成的任务）
+	group.Go(func() error {
+		log.Info("starting maintenance job runner")
+		maintenanceJobs.Run(groupCtx)
+		return nil
+	})
+
+	// 使用情况统计 goroutine（定期写入计数、清理过期数据，关闭时写入剩余计数；未启用时立即退出）
+	group.Go(func() error {
+		log.Info("starting analytics collector", zap.Bool("enabled", cfg.Analytics.Enabled), zap.Bool("anonymize", cfg.Analytics.Anonymize))
+		usageAnalytics.Run(groupCtx)
+		return nil
+	})
+
+	// 收信流量统计 goroutine（定期写入计数、清理过期数据，关闭时写入剩余计数；未启用时立即退出）
+	group.Go(func() error {
+		log.Info("starting mail flow recorder", zap.Bool("enabled", cfg.MailFlow.Enabled))
+		mailFlow.Run(groupCtx)
+		return nil
+	})
+
 	// 监控服务 goroutine
 	group.Go(func() error {
 		log.Info("starting monitoring services")
@@ -332,6 +1262,12 @@ func main() {
 		<-groupCtx.Done()
 		log.Info("shutdown signal received, gracefully shutting down...")
 
+		// SMTP 排空：立即停止接受新连接，新事务返回 421，进行中的投递在下方等待完成
+		smtpBackend.StartDrain()
+		if err := smtpListener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Warn("SMTP listener close warning", zap.Error(err))
+		}
+
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
@@ -340,23 +1276,114 @@ func main() {
 			log.Error("HTTP server shutdown error", zap.Error(err))
 		}
 
-		// 关闭 SMTP 服务器
+		// 等待进行中的 SMTP 投递完成（最长 DrainTimeout），之后断开剩余连接并取消会话上下文
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.SMTP.DrainTimeout)
+		if err := smtpBackend.WaitDrained(drainCtx); err != nil {
+			log.Warn("SMTP drain timed out, closing remaining sessions", zap.Error(err))
+		} else {
+			log.Info("SMTP sessions drained")
+		}
+		cancelDrain()
 		if err := smtpServer.Close(); err != nil {
 			log.Warn("SMTP server close warning", zap.Error(err))
 		}
+		cancelSMTPSessions()
+
+		// 关闭 POP3 服务器：空闲连接立即断开，执行中的命令完成后断开
+		if pop3Server != nil {
+			if err := pop3Server.Shutdown(shutdownCtx); err != nil {
+				log.Warn("POP3 server shutdown warning", zap.Error(err))
+			}
+		}
+
+		// 关闭 IMAP 服务器：空闲和 IDLE 中的连接收到 BYE 后断开
+		if imapServer != nil {
+			if err := imapServer.Shutdown(shutdownCtx); err != nil {
+				log.Warn("IMAP server shutdown warning", zap.Error(err))
+			}
+		}
+
+		// 关闭 gRPC 服务器：进行中的流结束，其他请求处理完毕
+		if grpcServer != nil {
+			if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+				log.Warn("gRPC server shutdown warning", zap.Error(err))
+			}
+		}
 
 		log.Info("servers stopped")
 		return nil
 	})
 
 	// 等待所有 goroutine 完成
-	if err := group.Wait(); err != nil && err != context.Canceled {
-		log.Fatal("server error", zap.Error(err))
+	waitErr := group.Wait()
+
+	// 等待入库流程发起的新邮件通知投递结束（未完成的事件留在发件箱，由重试任务补发）
+	outboxDispatcher.Wait()
+
+	// 导出尚未发送的 span
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Warn("tracing shutdown warning", zap.Error(err))
+	}
+	cancelTracing()
+
+	// 服务已停止，写入最后一次快照
+	if memStore != nil && cfg.Storage.SnapshotPath != "" {
+		if err := memStore.SaveSnapshot(cfg.Storage.SnapshotPath); err != nil {
+			log.Error("failed to write memory store snapshot on shutdown", zap.Error(err))
+		} else {
+			log.Info("memory store snapshot written", zap.String("path", cfg.Storage.SnapshotPath))
+		}
+	}
+
+	if waitErr != nil && waitErr != context.Canceled {
+		log.Fatal("server error", zap.Error(waitErr))
 	}
 
 	log.Info("server exited cleanly")
 }
 
+// newOAuthService 按配置注册第三方登录提供方，一个都没有配置时返回 nil
+func newOAuthService(cfg config.OAuthConfig, store storage.Store, log *zap.Logger) *auth.OAuthService {
+	oauthService := auth.NewOAuthService(store, store)
+	redirectURL := func(provider string) string {
+		return cfg.CallbackBaseURL + "/v1/auth/oauth/" + provider + "/callback"
+	}
+	if cfg.Google.ClientID != "" {
+		oauthService.Register(auth.NewOIDCProvider(auth.OIDCConfig{
+			Name:         "google",
+			IssuerURL:    auth.GoogleIssuer,
+			ClientID:     cfg.Google.ClientID,
+			ClientSecret: cfg.Google.ClientSecret,
+			RedirectURL:  redirectURL("google"),
+		}))
+	}
+	if cfg.GitHub.ClientID != "" {
+		oauthService.Register(auth.NewGitHubProvider(auth.GitHubConfig{
+			ClientID:     cfg.GitHub.ClientID,
+			ClientSecret: cfg.GitHub.ClientSecret,
+			RedirectURL:  redirectURL("github"),
+		}))
+	}
+	if cfg.OIDC.ClientID != "" {
+		oauthService.Register(auth.NewOIDCProvider(auth.OIDCConfig{
+			Name:         cfg.OIDC.Name,
+			IssuerURL:    cfg.OIDC.IssuerURL,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  redirectURL(cfg.OIDC.Name),
+			Scopes:       cfg.OIDC.Scopes,
+		}))
+	}
+
+	providers := oauthService.Providers()
+	if len(providers) == 0 {
+		return nil
+	}
+	log.Info("OAuth login enabled", zap.Strings("providers", providers))
+	return oauthService
+}
+
 // initializeSystemDomains 初始化系统域名
 //
 // 从配置文件中读取允许的域名列表，自动添加到系统域名中
@@ -372,7 +1399,7 @@ func initializeSystemDomains(systemDomainService *service.SystemDomainService, c
 	)
 
 	// 获取现有系统域名
-	existingDomains, err := systemDomainService.ListSystemDomains()
+	existingDomains, err := systemDomainService.ListSystemDomains(context.Background())
 	if err != nil {
 		log.Error("failed to list existing system domains", zap.Error(err))
 		return
@@ -418,7 +1445,7 @@ func initializeSystemDomains(systemDomainService *service.SystemDomainService, c
 		}
 
 		// 保存到存储
-		if err := systemDomainService.GetStore().SaveSystemDomain(sysDomain); err != nil {
+		if err := systemDomainService.GetStore().SaveSystemDomain(context.Background(), sysDomain); err != nil {
 			log.Error("failed to save system domain",
 				zap.String("domain", domainName),
 				zap.Error(err),
@@ -444,11 +1471,11 @@ func initializeSystemDomains(systemDomainService *service.SystemDomainService, c
 		defaultDomain := cfg.Mailbox.AllowedDomains[0]
 
 		// 查找该域名的ID
-		updatedDomains, err := systemDomainService.ListSystemDomains()
+		updatedDomains, err := systemDomainService.ListSystemDomains(context.Background())
 		if err == nil {
 			for _, d := range updatedDomains {
 				if d.Domain == defaultDomain {
-					if err := systemDomainService.SetDefaultDomain(d.ID); err != nil {
+					if err := systemDomainService.SetDefaultDomain(context.Background(), d.ID); err != nil {
 						log.Error("failed to set default domain",
 							zap.String("domain", defaultDomain),
 							zap.Error(err),
@@ -474,7 +1501,7 @@ func createDefaultAdmin(store storage.Store, log *zap.Logger) {
 	username := "admin"
 
 	// 检查管理员是否已存在
-	if _, err := store.GetUserByEmail(email); err == nil {
+	if _, err := store.GetUserByEmail(context.Background(), email); err == nil {
 		log.Info("默认管理员用户已存在，跳过创建", zap.String("email", email))
 		return
 	}
@@ -500,7 +1527,7 @@ func createDefaultAdmin(store storage.Store, log *zap.Logger) {
 		UpdatedAt:       time.Now(),
 	}
 
-	if err := store.CreateUser(user); err != nil {
+	if err := store.CreateUser(context.Background(), user); err != nil {
 		log.Error("创建默认管理员失败", zap.Error(err))
 		return
 	}
@@ -512,8 +1539,44 @@ func createDefaultAdmin(store storage.Store, log *zap.Logger) {
 	)
 }
 
+// newTaskCoordinator 创建定时任务协调器
+//
+// 数据库存储且启用 Redis 时用 Redis 锁在实例间互斥；内存存储各实例数据独立，未启用 Redis 时
+// 只支持单实例部署，两者都使用进程内锁。连不上 Redis 时退回进程内锁，多实例会重复执行任务。
+func newTaskCoordinator(cfg *config.Config, shared bool, log *zap.Logger) (*leader.Coordinator, func()) {
+	if !shared || !cfg.Redis.Enabled {
+		return leader.NewCoordinator(nil, nil, log), func() {}
+	}
+
+	client, err := storageredis.New(&cfg.Redis)
+	if err != nil {
+		log.Warn("redis unavailable for task locks, periodic tasks run on every instance",
+			zap.String("redis_address", cfg.Redis.Address),
+			zap.Error(err),
+		)
+		return leader.NewCoordinator(nil, nil, log), func() {}
+	}
+
+	tasks := leader.NewCoordinator(nil, storageredis.NewLocker(client.Client()), log)
+	log.Info("periodic tasks coordinated via redis locks", zap.String("owner", tasks.Owner()))
+	return tasks, func() { _ = client.Close() }
+}
+
 // initializeDatabaseStorage 初始化数据库存储
 func initializeDatabaseStorage(cfg *config.Config, log *zap.Logger) (storage.Store, error) {
+	if !cfg.Redis.Enabled {
+		// 单机模式：黑名单、限流、会话、系统配置和黑洞统计由进程内存储代替 Redis
+		log.Info("initializing database storage without redis (standalone mode)",
+			zap.String("database_type", cfg.Database.Type),
+			zap.Strings("in_process", []string{"jwt_blacklist", "rate_limit", "session", "system_config", "sink_stats"}),
+		)
+		store, err := hybrid.NewStandaloneStore(cfg.Database.Type, cfg.Database.DSN, memory.NewStore(cfg.Mailbox.DefaultTTL))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create standalone store: %w", err)
+		}
+		return store, nil
+	}
+
 	log.Info("initializing database storage",
 		zap.String("database_type", cfg.Database.Type),
 		zap.String("redis_address", cfg.Redis.Address),
@@ -527,6 +1590,14 @@ func initializeDatabaseStorage(cfg *config.Config, log *zap.Logger) (storage.Sto
 		cfg.Redis.Password,
 		cfg.Redis.DB,
 	)
+	if errors.Is(err, hybrid.ErrCacheUnavailable) {
+		// Redis 是可选依赖，不阻塞启动：退回进程内缓存（多实例间不共享黑名单和限流）
+		log.Warn("redis unavailable, falling back to in-process cache",
+			zap.String("redis_address", cfg.Redis.Address),
+			zap.Error(err),
+		)
+		store, err = hybrid.NewStandaloneStore(cfg.Database.Type, cfg.Database.DSN, memory.NewStore(cfg.Mailbox.DefaultTTL))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create hybrid store: %w", err)
 	}
@@ -537,3 +1608,16 @@ func initializeDatabaseStorage(cfg *config.Config, log *zap.Logger) (storage.Sto
 
 	return store, nil
 }
+
+// isTransientFSError 文件存储目录创建失败是否值得重试（卷挂载中的权限或 IO 错误）
+// contentBackend 邮件内容存储后端（filesystem.Store 或 objectstore.Store）
+type contentBackend interface {
+	service.FilesystemStore
+	service.MailboxContentStore
+	monitoring.UptimeStore
+}
+
+func isTransientFSError(err error) bool {
+	return errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EIO) ||
+		errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.EAGAIN)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"tempmail/backend/pkg/client"
+)
+
+func newCreateCommand(opts *options) *cobra.Command {
+	var req client.CreateMailboxRequest
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "创建邮箱",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			mailbox, err := opts.client().CreateMailbox(cmd.Context(), req)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if opts.json {
+				return printJSON(out, mailbox)
+			}
+			fmt.Fprintf(out, "地址: %s\nID:   %s\n令牌: %s\n", mailbox.Address, mailbox.ID, mailbox.Token)
+			if mailbox.ExpiresAt != nil {
+				fmt.Fprintf(out, "过期: %s\n", mailbox.ExpiresAt.Local().Format(time.DateTime))
+			}
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&req.Prefix, "prefix", "", "地址前缀（默认随机）")
+	flags.StringVar(&req.Domain, "domain", "", "域名（默认使用服务端默认域名）")
+	flags.DurationVar(&req.ExpiresIn, "ttl", 0, "有效期，如 30m（默认使用服务端默认有效期）")
+	return cmd
+}
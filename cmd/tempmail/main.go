@@ -0,0 +1,93 @@
+// Command tempmail 临时邮箱命令行工具，供 shell 脚本和 CI 使用
+//
+//	export TEMPMAIL_SERVER=https://api.example.com TEMPMAIL_API_KEY=tk_...
+//	ID=$(tempmail create --json | jq -r .id)
+//	CODE=$(tempmail otp "$ID" --wait 2m)
+//	tempmail tail "$ID"
+//	tempmail raw "$ID" > latest.eml
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"tempmail/backend/pkg/client"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := newRootCommand().ExecuteContext(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// options 全局参数
+type options struct {
+	server string // 服务地址
+	apiKey string // API Key（创建的邮箱归属 Key 所属用户，并可访问其邮箱）
+	token  string // 邮箱令牌（访问不属于自己的邮箱时使用）
+	json   bool   // 以 JSON 输出
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &options{}
+	root := &cobra.Command{
+		Use:           "tempmail",
+		Short:         "临时邮箱命令行工具",
+		Long:          "创建临时邮箱、实时查看新邮件、获取验证码和导出原始邮件。\n参数也可以通过环境变量 TEMPMAIL_SERVER、TEMPMAIL_API_KEY 和 TEMPMAIL_MAILBOX_TOKEN 设置。",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.server, "server", envOr("TEMPMAIL_SERVER", "http://localhost:8080"), "服务地址")
+	flags.StringVar(&opts.apiKey, "api-key", os.Getenv("TEMPMAIL_API_KEY"), "API Key")
+	flags.StringVar(&opts.token, "token", os.Getenv("TEMPMAIL_MAILBOX_TOKEN"), "邮箱令牌（访问不属于 API Key 用户的邮箱时使用）")
+	flags.BoolVar(&opts.json, "json", false, "以 JSON 输出")
+
+	root.AddCommand(
+		newCreateCommand(opts),
+		newListCommand(opts),
+		newTailCommand(opts),
+		newOTPCommand(opts),
+		newRawCommand(opts),
+	)
+	return root
+}
+
+// client 按全局参数创建 API 客户端
+func (o *options) client() *client.Client {
+	c := client.New(o.server)
+	c.SetAPIKey(o.apiKey)
+	return c
+}
+
+// mailbox 要访问的邮箱：有 --token 时凭邮箱令牌，否则凭 API Key
+func (o *options) mailbox(id string) (*client.Mailbox, error) {
+	if o.token == "" && o.apiKey == "" {
+		return nil, errors.New("需要 --api-key 或 --token")
+	}
+	return &client.Mailbox{ID: id, Token: o.token}, nil
+}
+
+// printJSON 以一行 JSON 输出
+func printJSON(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
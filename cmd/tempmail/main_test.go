@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+	httptransport "tempmail/backend/internal/transport/http"
+	"tempmail/backend/internal/websocket"
+)
+
+// syncBuffer 可并发读写的输出缓冲（tail 在后台写入）
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type testEnv struct {
+	server   string
+	apiKey   string
+	messages *service.MessageService
+	hub      *websocket.Hub
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore(time.Hour)
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.example"}, DefaultTTL: time.Hour}}
+	cfg.CORS.AllowedOrigins = []string{"*"}
+
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: "user-1", Email: "dev@example.com", Tier: domain.TierFree, IsActive: true}))
+	apiKeys := service.NewAPIKeyService(store)
+	key, err := apiKeys.CreateAPIKey(t.Context(), service.CreateAPIKeyInput{UserID: "user-1", Name: "cli"})
+	require.NoError(t, err)
+
+	manager := jwtpkg.NewManager("test-secret-test-secret-test-secret", "tempmail", 15*time.Minute, time.Hour)
+	hub := websocket.NewHub(nil, manager, store)
+	go hub.Run(t.Context())
+
+	messages := service.NewMessageService(store)
+	server := httptest.NewServer(httptransport.NewRouter(httptransport.RouterDependencies{
+		Config:         cfg,
+		MailboxService: service.NewMailboxService(store, store, cfg),
+		MessageService: messages,
+		APIKeyService:  apiKeys,
+		JWTManager:     manager,
+		WebSocketHub:   hub,
+		Store:          store,
+	}))
+	t.Cleanup(server.Close)
+	return &testEnv{server: server.URL, apiKey: key.Key, messages: messages, hub: hub}
+}
+
+// run 执行命令，返回标准输出
+func (env *testEnv) run(ctx context.Context, out *syncBuffer, args ...string) error {
+	cmd := newRootCommand()
+	cmd.SetArgs(append([]string{"--server", env.server, "--api-key", env.apiKey}, args...))
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	return cmd.ExecuteContext(ctx)
+}
+
+func TestCLI(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := t.Context()
+
+	var out syncBuffer
+	require.NoError(t, env.run(ctx, &out, "create", "--prefix", "ci-run", "--ttl", "30m"))
+	assert.Contains(t, out.String(), "地址: ci-run@temp.example")
+	var mailboxID string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if id, ok := strings.CutPrefix(line, "ID:"); ok {
+			mailboxID = strings.TrimSpace(id)
+		}
+	}
+	require.NotEmpty(t, mailboxID)
+
+	t.Run("API Key 访问自己的邮箱", func(t *testing.T) {
+		var out syncBuffer
+		err := env.run(ctx, &out, "otp", mailboxID)
+		assert.EqualError(t, err, "没有找到验证码")
+
+		cmd := newRootCommand()
+		cmd.SetArgs([]string{"--server", env.server, "--api-key", "wrong", "list", mailboxID})
+		cmd.SetOut(&out)
+		assert.Error(t, cmd.ExecuteContext(ctx), "无效的 API Key")
+	})
+
+	t.Run("实时输出新邮件", func(t *testing.T) {
+		tailCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var out syncBuffer
+		done := make(chan error, 1)
+		go func() { done <- env.run(tailCtx, &out, "tail", mailboxID) }()
+
+		require.Eventually(t, func() bool { return env.hub.ConnectionCount() == 1 }, 2*time.Second, 10*time.Millisecond)
+		message, err := env.messages.Create(ctx, service.CreateMessageInput{
+			MailboxID: mailboxID, From: "noreply@example.com", To: "ci-run@temp.example", Subject: "Verify your account",
+			Text: "Your verification code is 582014", Raw: "Subject: Verify your account\r\n\r\nYour verification code is 582014\r\n",
+		})
+		require.NoError(t, err)
+		env.hub.NotifyNewMail(tailCtx, mailboxID, message)
+
+		assert.Eventually(t, func() bool { return strings.Contains(out.String(), "Verify your account") }, 2*time.Second, 10*time.Millisecond)
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("输出验证码", func(t *testing.T) {
+		var out syncBuffer
+		require.NoError(t, env.run(ctx, &out, "otp", mailboxID, "--wait", "1s"))
+		assert.Equal(t, "582014\n", out.String())
+	})
+
+	t.Run("导出原始邮件", func(t *testing.T) {
+		var out syncBuffer
+		require.NoError(t, env.run(ctx, &out, "raw", mailboxID))
+		assert.Contains(t, out.String(), "Subject: Verify your account")
+
+		path := filepath.Join(t.TempDir(), "latest.eml")
+		require.NoError(t, env.run(ctx, &out, "raw", mailboxID, "-o", path))
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(raw), "582014")
+	})
+}
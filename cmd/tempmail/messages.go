@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"tempmail/backend/pkg/client"
+)
+
+func newListCommand(opts *options) *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "list <邮箱ID>",
+		Short: "列出邮件（最新的在前）",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mailbox, err := opts.mailbox(args[0])
+			if err != nil {
+				return err
+			}
+			list, err := opts.client().ListMessages(cmd.Context(), mailbox, client.ListOptions{Limit: limit})
+			if err != nil {
+				return err
+			}
+			if opts.json {
+				return printJSON(cmd.OutOrStdout(), list.Items)
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SEQ\tID\t接收时间\t发件人\t主题")
+			for _, m := range list.Items {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", m.Seq, m.ID, m.ReceivedAt.Local().Format(time.DateTime), m.From, m.Subject)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 20, "最多列出的邮件数（最多 200）")
+	return cmd
+}
+
+func newRawCommand(opts *options) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "raw <邮箱ID> [邮件ID]",
+		Short: "输出原始邮件（默认最新一封）",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mailbox, err := opts.mailbox(args[0])
+			if err != nil {
+				return err
+			}
+			c := opts.client()
+			var messageID string
+			if len(args) == 2 {
+				messageID = args[1]
+			} else {
+				list, err := c.ListMessages(cmd.Context(), mailbox, client.ListOptions{Limit: 1})
+				if err != nil {
+					return err
+				}
+				if len(list.Items) == 0 {
+					return errors.New("邮箱中没有邮件")
+				}
+				messageID = list.Items[0].ID
+			}
+
+			raw, err := c.GetMessageRaw(cmd.Context(), mailbox, messageID)
+			if err != nil {
+				return err
+			}
+			if output != "" {
+				return os.WriteFile(output, raw, 0o644)
+			}
+			_, err = cmd.OutOrStdout().Write(raw)
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "写入文件而不是标准输出")
+	return cmd
+}
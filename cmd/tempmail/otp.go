@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"tempmail/backend/pkg/client"
+)
+
+func newOTPCommand(opts *options) *cobra.Command {
+	var (
+		wait  time.Duration
+		since time.Duration
+		link  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "otp <邮箱ID>",
+		Short: "输出最新一封邮件中的验证码",
+		Long:  "输出最新一封带验证码的邮件中最可能的验证码（--link 时输出第一个验证链接）。\n--wait 时轮询等待，超时以状态 1 退出。",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mailbox, err := opts.mailbox(args[0])
+			if err != nil {
+				return err
+			}
+			var after time.Time
+			if since > 0 {
+				after = time.Now().Add(-since)
+			}
+
+			c := opts.client()
+			var otp *client.OTP
+			if wait > 0 {
+				otp, err = c.WaitForOTP(cmd.Context(), mailbox, client.WaitOptions{Timeout: wait, Since: after})
+			} else {
+				otp, err = c.LatestOTP(cmd.Context(), mailbox, after)
+			}
+			if client.IsNotFound(err) || errors.Is(err, client.ErrWaitTimeout) {
+				return errors.New("没有找到验证码")
+			}
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if opts.json {
+				return printJSON(out, otp)
+			}
+			value := otp.Code
+			if link {
+				value = ""
+				if len(otp.Links) > 0 {
+					value = otp.Links[0]
+				}
+			}
+			if value == "" {
+				return errors.New("最新的邮件中没有验证码")
+			}
+			fmt.Fprintln(out, value)
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.DurationVar(&wait, "wait", 0, "没有验证码时最长等待的时间，如 2m")
+	flags.DurationVar(&since, "since", 0, "只查找最近这段时间内收到的邮件，如 5m")
+	flags.BoolVar(&link, "link", false, "输出验证链接而不是验证码")
+	return cmd
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"tempmail/backend/pkg/wsclient"
+	"tempmail/backend/pkg/wsproto"
+)
+
+func newTailCommand(opts *options) *cobra.Command {
+	var since int64
+	cmd := &cobra.Command{
+		Use:   "tail <邮箱ID>",
+		Short: "通过 WebSocket 实时输出新邮件，直到中断或邮箱被删除",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mailbox, err := opts.mailbox(args[0])
+			if err != nil {
+				return err
+			}
+			// WebSocket 凭邮箱令牌订阅；只有 API Key 时先查询邮箱取得令牌
+			if mailbox.Token == "" {
+				info, err := opts.client().GetMailbox(cmd.Context(), mailbox)
+				if err != nil {
+					return err
+				}
+				mailbox.Token = info.Token
+			}
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			ws, err := wsclient.Connect(ctx, websocketURL(opts.server), wsclient.Auth{Token: mailbox.Token, MailboxID: mailbox.ID})
+			if err != nil {
+				return err
+			}
+			if err := ws.Subscribe(mailbox.ID, since); err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for event := range ws.Events() {
+				switch event.Type {
+				case wsproto.MessageTypeNewMail:
+					if opts.json {
+						if err := printJSON(out, event.NewMail); err != nil {
+							return err
+						}
+						continue
+					}
+					mail := event.NewMail
+					fmt.Fprintf(out, "%s  #%d  %s  %s\n", receivedAt(mail.CreatedAt), mail.Seq, mail.From, mail.Subject)
+				case wsproto.MessageTypeMailboxDeleted:
+					return errors.New("邮箱已被删除")
+				case wsproto.MessageTypeMailboxSuspended:
+					return errors.New("邮箱已被停用")
+				case wsproto.MessageTypeError:
+					fmt.Fprintf(cmd.ErrOrStderr(), "警告: %s\n", event.Error)
+				}
+			}
+			if err := ws.Err(); err != nil && !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Int64Var(&since, "since", 0, "从此序号之后开始（默认补发服务端保留的最近邮件）")
+	return cmd
+}
+
+// websocketURL 由服务地址得到 WebSocket 地址
+func websocketURL(server string) string {
+	server = strings.TrimRight(server, "/")
+	switch {
+	case strings.HasPrefix(server, "https://"):
+		server = "wss://" + strings.TrimPrefix(server, "https://")
+	case strings.HasPrefix(server, "http://"):
+		server = "ws://" + strings.TrimPrefix(server, "http://")
+	}
+	return server + "/v1/ws"
+}
+
+// receivedAt 格式化 RFC 3339 时间为本地时间
+func receivedAt(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,325 @@
+// Package analytics 收集只含汇总计数的使用情况统计。
+//
+// 收集器订阅邮箱创建、新邮件入库等事件，在内存中按 UTC 小时（每日指标按天）合并计数，定期写入主存储；
+// 不保存邮箱地址、IP 等可识别个人的数据。按发件人统计时，地址先用每日随机生成、只保存在内存中的盐做
+// HMAC，次日换盐后无法再把哈希和地址对应起来；匿名模式下连哈希后的发件人统计也不记录。
+package analytics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// 功能调用统计的维度（AnalyticsFeatureUsage）
+const (
+	FeatureSearch         = "search"          // 邮件搜索
+	FeatureExport         = "export"          // 个人数据导出
+	FeatureWebhookCreated = "webhook_created" // 创建 Webhook
+)
+
+const (
+	// DefaultRetention 默认保留期限
+	DefaultRetention = 400 * 24 * time.Hour
+	// DefaultFlushInterval 默认写入间隔
+	DefaultFlushInterval = time.Minute
+	// sampleInterval WebSocket 在线连接数采样间隔
+	sampleInterval = 10 * time.Second
+	// flushTimeout 关闭时最后一次写入的超时
+	flushTimeout = 5 * time.Second
+)
+
+// ConnectionCounter 当前在线连接数（WebSocket Hub 实现）
+type ConnectionCounter interface {
+	ConnectionCount() int
+}
+
+// bucketKey 内存中待写入的计数
+type bucketKey struct {
+	metric    string
+	dimension string
+	bucket    time.Time
+}
+
+// Collector 使用情况统计收集器
+//
+// 实现 service.MailboxCreatedNotifier 和 service.MailReceivedNotifier；未启用时所有记录方法为空操作，
+// 不写入任何数据。
+type Collector struct {
+	store         storage.AnalyticsRepository
+	log           *zap.Logger
+	now           func() time.Time
+	random        io.Reader // 盐的随机来源
+	enabled       bool
+	anonymize     bool
+	retention     time.Duration
+	flushInterval time.Duration
+	connections   ConnectionCounter // 在线连接数（可选）
+
+	mu        sync.Mutex
+	pending   map[bucketKey]int64 // 尚未写入的计数
+	activeDay time.Time           // active 所属的 UTC 日期
+	active    map[string]struct{} // 当天活跃的邮箱 ID（只在内存中，用于去重）
+	saltDay   time.Time           // salt 所属的 UTC 日期
+	salt      []byte
+	lastPrune time.Time
+}
+
+// NewCollector 创建统计收集器
+func NewCollector(store storage.AnalyticsRepository, cfg config.AnalyticsConfig, log *zap.Logger) *Collector {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	retention := cfg.Retention
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	flushInterval := cfg.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+	return &Collector{
+		store:         store,
+		log:           log,
+		now:           time.Now,
+		random:        rand.Reader,
+		enabled:       cfg.Enabled,
+		anonymize:     cfg.Anonymize,
+		retention:     retention,
+		flushInterval: flushInterval,
+		pending:       make(map[bucketKey]int64),
+		active:        make(map[string]struct{}),
+	}
+}
+
+// SetClock 设置时间来源（测试用）
+func (c *Collector) SetClock(now func() time.Time) {
+	c.now = now
+}
+
+// SetConnectionCounter 设置在线连接数来源，用于统计 WebSocket 连接峰值
+func (c *Collector) SetConnectionCounter(counter ConnectionCounter) {
+	c.connections = counter
+}
+
+// Enabled 是否收集统计
+func (c *Collector) Enabled() bool {
+	return c.enabled
+}
+
+// NotifyMailboxCreated 记录邮箱创建（按域名、按创建方式），并计入当天活跃邮箱
+func (c *Collector) NotifyMailboxCreated(mailbox *domain.Mailbox) {
+	if !c.enabled || mailbox == nil {
+		return
+	}
+	auth := "guest"
+	if mailbox.UserID != nil {
+		auth = "user"
+	}
+
+	now := c.now().UTC()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addLocked(domain.AnalyticsMailboxesCreatedByDomain, strings.ToLower(mailbox.Domain), now, 1)
+	c.addLocked(domain.AnalyticsMailboxesCreatedByAuth, auth, now, 1)
+	c.markActiveLocked(mailbox.ID, now)
+}
+
+// NotifyMailReceived 记录新邮件入库（按收件域名、按发件人哈希），并计入当天活跃邮箱
+func (c *Collector) NotifyMailReceived(message *domain.Message) {
+	if !c.enabled || message == nil {
+		return
+	}
+
+	now := c.now().UTC()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if recipientDomain := addressDomain(message.To); recipientDomain != "" {
+		c.addLocked(domain.AnalyticsMessagesByDomain, recipientDomain, now, 1)
+	}
+	if sender := normalizeAddress(message.From); sender != "" && !c.anonymize {
+		c.addLocked(domain.AnalyticsMessagesBySender, c.hashLocked(sender, now), now, 1)
+	}
+	c.markActiveLocked(message.MailboxID, now)
+}
+
+// RecordFeature 记录一次功能调用
+func (c *Collector) RecordFeature(feature string) {
+	if !c.enabled {
+		return
+	}
+	now := c.now().UTC()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addLocked(domain.AnalyticsFeatureUsage, feature, now, 1)
+}
+
+// Sample 采样当前在线连接数，记录每小时峰值
+func (c *Collector) Sample() {
+	if !c.enabled || c.connections == nil {
+		return
+	}
+	count := int64(c.connections.ConnectionCount())
+	now := c.now().UTC()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mergeLocked(bucketKey{metric: domain.AnalyticsWebSocketPeak, bucket: now.Truncate(time.Hour)}, count)
+}
+
+// addLocked 计数类指标在所属小时桶内累加
+func (c *Collector) addLocked(metric, dimension string, at time.Time, delta int64) {
+	c.mergeLocked(bucketKey{metric: metric, dimension: dimension, bucket: at.Truncate(time.Hour)}, delta)
+}
+
+// mergeLocked 合并计数：峰值类指标取最大值，其余累加
+func (c *Collector) mergeLocked(key bucketKey, value int64) {
+	if domain.IsPeakAnalyticsMetric(key.metric) {
+		c.pending[key] = max(c.pending[key], value)
+		return
+	}
+	c.pending[key] += value
+}
+
+// markActiveLocked 记录邮箱当天活跃，活跃数以峰值方式合并（重启后当天的去重集合清空，只会少计不会重复计）
+func (c *Collector) markActiveLocked(mailboxID string, now time.Time) {
+	if mailboxID == "" {
+		return
+	}
+	day := now.Truncate(24 * time.Hour)
+	if !day.Equal(c.activeDay) {
+		c.activeDay = day
+		c.active = make(map[string]struct{})
+	}
+	c.active[mailboxID] = struct{}{}
+	c.mergeLocked(bucketKey{metric: domain.AnalyticsMailboxesActive, bucket: day}, int64(len(c.active)))
+}
+
+// hashLocked 用当天的盐计算地址的 HMAC，跨天换盐后旧盐即丢弃
+func (c *Collector) hashLocked(address string, now time.Time) string {
+	day := now.Truncate(24 * time.Hour)
+	if c.salt == nil || !day.Equal(c.saltDay) {
+		salt := make([]byte, 32)
+		if _, err := io.ReadFull(c.random, salt); err != nil {
+			c.log.Warn("failed to generate analytics salt", zap.Error(err))
+		}
+		c.salt, c.saltDay = salt, day
+	}
+	mac := hmac.New(sha256.New, c.salt)
+	mac.Write([]byte(address))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// Flush 把内存中的计数写入存储，失败时计数保留到下次写入
+func (c *Collector) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	if len(c.pending) == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	pending := c.pending
+	c.pending = make(map[bucketKey]int64)
+	c.mu.Unlock()
+
+	buckets := make([]domain.AnalyticsBucket, 0, len(pending))
+	for key, value := range pending {
+		buckets = append(buckets, domain.AnalyticsBucket{Metric: key.metric, Dimension: key.dimension, Bucket: key.bucket, Value: value})
+	}
+	if err := c.store.MergeAnalyticsBuckets(ctx, buckets); err != nil {
+		c.mu.Lock()
+		for key, value := range pending {
+			c.mergeLocked(key, value)
+		}
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Prune 删除超过保留期限的统计数据
+func (c *Collector) Prune(ctx context.Context) (int64, error) {
+	cutoff := c.now().UTC().Add(-c.retention).Truncate(time.Hour)
+	return c.store.DeleteAnalyticsBucketsBefore(ctx, cutoff)
+}
+
+// Run 定期采样在线连接数、写入计数并清理过期数据，ctx 取消时写入剩余计数后返回；未启用时立即返回
+func (c *Collector) Run(ctx context.Context) {
+	if !c.enabled {
+		return
+	}
+
+	c.prune(ctx)
+	flushTicker := time.NewTicker(c.flushInterval)
+	defer flushTicker.Stop()
+	sampleTicker := time.NewTicker(min(sampleInterval, c.flushInterval))
+	defer sampleTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.Sample()
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+			if err := c.Flush(flushCtx); err != nil {
+				c.log.Warn("failed to flush analytics on shutdown", zap.Error(err))
+			}
+			cancel()
+			return
+		case <-sampleTicker.C:
+			c.Sample()
+		case <-flushTicker.C:
+			if err := c.Flush(ctx); err != nil {
+				c.log.Warn("failed to flush analytics", zap.Error(err))
+			}
+			if c.now().Sub(c.lastPrune) >= 24*time.Hour {
+				c.prune(ctx)
+			}
+		}
+	}
+}
+
+// prune 清理过期数据（失败只记日志，下个周期重试）
+func (c *Collector) prune(ctx context.Context) {
+	deleted, err := c.Prune(ctx)
+	if err != nil {
+		c.log.Warn("failed to prune analytics", zap.Error(err))
+		return
+	}
+	c.lastPrune = c.now()
+	if deleted > 0 {
+		c.log.Info("pruned analytics buckets", zap.Int64("deleted", deleted), zap.Duration("retention", c.retention))
+	}
+}
+
+// normalizeAddress 解析邮件头中的地址（可能带显示名或多个地址，取第一个），统一为小写
+func normalizeAddress(header string) string {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return ""
+	}
+	if list, err := mail.ParseAddressList(header); err == nil && len(list) > 0 {
+		return strings.ToLower(list[0].Address)
+	}
+	return strings.ToLower(header)
+}
+
+// addressDomain 地址的域名部分（小写），无法解析时为空
+func addressDomain(header string) string {
+	address := normalizeAddress(header)
+	at := strings.LastIndex(address, "@")
+	if at < 0 || at == len(address)-1 {
+		return ""
+	}
+	return strings.Trim(address[at+1:], "> ")
+}
@@ -0,0 +1,252 @@
+package analytics
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// countingStore 记录写入次数的统计存储
+type countingStore struct {
+	*memory.Store
+	writes atomic.Int64
+}
+
+func (s *countingStore) MergeAnalyticsBuckets(ctx context.Context, buckets []domain.AnalyticsBucket) error {
+	s.writes.Add(1)
+	return s.Store.MergeAnalyticsBuckets(ctx, buckets)
+}
+
+func (s *countingStore) DeleteAnalyticsBucketsBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.writes.Add(1)
+	return s.Store.DeleteAnalyticsBucketsBefore(ctx, before)
+}
+
+// fixedConnections 固定的在线连接数
+type fixedConnections struct{ n atomic.Int64 }
+
+func (f *fixedConnections) ConnectionCount() int { return int(f.n.Load()) }
+
+func newTestCollector(cfg config.AnalyticsConfig) (*Collector, *countingStore, *time.Time) {
+	store := &countingStore{Store: memory.NewStore(time.Hour)}
+	collector := NewCollector(store, cfg, nil)
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	collector.SetClock(func() time.Time { return now })
+	return collector, store, &now
+}
+
+func userID(id string) *string { return &id }
+
+func seriesByDimension(report *Report) map[string]Series {
+	result := make(map[string]Series, len(report.Series))
+	for _, series := range report.Series {
+		result[series.Dimension] = series
+	}
+	return result
+}
+
+func TestCollector(t *testing.T) {
+	t.Run("模拟一天的事件按小时和按天汇总", func(t *testing.T) {
+		collector, _, now := newTestCollector(config.AnalyticsConfig{Enabled: true})
+		connections := &fixedConnections{}
+		collector.SetConnectionCounter(connections)
+		day := *now
+
+		// 00:10 游客在 temp.mail 创建邮箱并收到两封邮件
+		*now = day.Add(10 * time.Minute)
+		collector.NotifyMailboxCreated(&domain.Mailbox{ID: "mb-1", Domain: "Temp.Mail"})
+		collector.NotifyMailReceived(&domain.Message{MailboxID: "mb-1", From: "Alerts <alerts@shop.example>", To: "box1@temp.mail"})
+		collector.NotifyMailReceived(&domain.Message{MailboxID: "mb-1", From: "alerts@shop.example", To: "box1@temp.mail"})
+		connections.n.Store(3)
+		collector.Sample()
+		require.NoError(t, collector.Flush(t.Context()))
+
+		// 09:30 登录用户在 corp.example 创建邮箱，另一个邮箱收到邮件，使用搜索
+		*now = day.Add(9*time.Hour + 30*time.Minute)
+		collector.NotifyMailboxCreated(&domain.Mailbox{ID: "mb-2", Domain: "corp.example", UserID: userID("u-1")})
+		collector.NotifyMailReceived(&domain.Message{MailboxID: "mb-3", From: "news@paper.example", To: "inbox@corp.example"})
+		collector.NotifyMailReceived(&domain.Message{MailboxID: "mb-1", From: "alerts@shop.example", To: "box1@temp.mail"})
+		collector.RecordFeature(FeatureSearch)
+		collector.RecordFeature(FeatureSearch)
+		connections.n.Store(7)
+		collector.Sample()
+		connections.n.Store(2)
+		collector.Sample()
+		require.NoError(t, collector.Flush(t.Context()))
+
+		// 23:59 游客邮箱，再导出一次
+		*now = day.Add(24*time.Hour - time.Minute)
+		collector.NotifyMailboxCreated(&domain.Mailbox{ID: "mb-4", Domain: "temp.mail"})
+		collector.RecordFeature(FeatureExport)
+		require.NoError(t, collector.Flush(t.Context()))
+
+		// 次日 00:05 的事件计入下一天
+		*now = day.Add(24*time.Hour + 5*time.Minute)
+		collector.NotifyMailboxCreated(&domain.Mailbox{ID: "mb-5", Domain: "temp.mail"})
+		require.NoError(t, collector.Flush(t.Context()))
+
+		hourly, err := collector.Query(t.Context(), Query{Metric: domain.AnalyticsMessagesByDomain, Interval: IntervalHour, From: day, To: day.Add(24 * time.Hour)})
+		require.NoError(t, err)
+		assert.Len(t, hourly.Series[0].Points, 24, "缺少数据的小时补零")
+		byDomain := seriesByDimension(hourly)
+		assert.Equal(t, int64(3), byDomain["temp.mail"].Total)
+		assert.Equal(t, int64(2), byDomain["temp.mail"].Points[0].Value)
+		assert.Equal(t, int64(1), byDomain["temp.mail"].Points[9].Value)
+		assert.Equal(t, int64(1), byDomain["corp.example"].Total)
+		assert.Equal(t, "temp.mail", hourly.Series[0].Dimension, "按合计值排序")
+
+		daily, err := collector.Query(t.Context(), Query{Metric: domain.AnalyticsMailboxesCreatedByDomain, From: day, To: day.Add(48 * time.Hour)})
+		require.NoError(t, err)
+		created := seriesByDimension(daily)
+		require.Len(t, created["temp.mail"].Points, 2)
+		assert.Equal(t, int64(2), created["temp.mail"].Points[0].Value, "域名统一为小写")
+		assert.Equal(t, int64(1), created["temp.mail"].Points[1].Value)
+		assert.Equal(t, int64(1), created["corp.example"].Points[0].Value)
+
+		auth, err := collector.Query(t.Context(), Query{Metric: domain.AnalyticsMailboxesCreatedByAuth, From: day, To: day.Add(24 * time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), seriesByDimension(auth)["guest"].Total)
+		assert.Equal(t, int64(1), seriesByDimension(auth)["user"].Total)
+
+		active, err := collector.Query(t.Context(), Query{Metric: domain.AnalyticsMailboxesActive, From: day, To: day.Add(48 * time.Hour)})
+		require.NoError(t, err)
+		require.Len(t, active.Series, 1)
+		assert.Equal(t, int64(4), active.Series[0].Points[0].Value, "mb-1 当天多次活跃只计一次")
+		assert.Equal(t, int64(1), active.Series[0].Points[1].Value, "次日重新计数")
+
+		features, err := collector.Query(t.Context(), Query{Metric: domain.AnalyticsFeatureUsage, From: day, To: day.Add(24 * time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), seriesByDimension(features)[FeatureSearch].Total)
+		assert.Equal(t, int64(1), seriesByDimension(features)[FeatureExport].Total)
+
+		peak, err := collector.Query(t.Context(), Query{Metric: domain.AnalyticsWebSocketPeak, Interval: IntervalHour, From: day, To: day.Add(24 * time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), peak.Series[0].Points[0].Value)
+		assert.Equal(t, int64(7), peak.Series[0].Points[9].Value, "同一小时取最大值")
+		assert.Equal(t, int64(7), peak.Series[0].Total)
+
+		senders, err := collector.Query(t.Context(), Query{Metric: domain.AnalyticsMessagesBySender, From: day, To: day.Add(24 * time.Hour)})
+		require.NoError(t, err)
+		require.Len(t, senders.Series, 2)
+		assert.Equal(t, int64(3), senders.Series[0].Total, "同一发件人（含显示名、大小写不同）计为同一哈希")
+		for _, series := range senders.Series {
+			assert.Len(t, series.Dimension, 16)
+			assert.NotContains(t, series.Dimension, "@")
+		}
+	})
+
+	t.Run("发件人哈希按天换盐", func(t *testing.T) {
+		collector, _, now := newTestCollector(config.AnalyticsConfig{Enabled: true})
+		day := *now
+
+		collector.mu.Lock()
+		morning := collector.hashLocked("alerts@shop.example", day.Add(time.Hour))
+		evening := collector.hashLocked("alerts@shop.example", day.Add(23*time.Hour))
+		other := collector.hashLocked("news@paper.example", day.Add(23*time.Hour))
+		nextDay := collector.hashLocked("alerts@shop.example", day.Add(25*time.Hour))
+		collector.mu.Unlock()
+
+		assert.Equal(t, morning, evening, "同一天哈希不变")
+		assert.NotEqual(t, morning, other)
+		assert.NotEqual(t, morning, nextDay, "跨天后哈希改变")
+	})
+
+	t.Run("匿名模式不记录发件人统计", func(t *testing.T) {
+		collector, _, now := newTestCollector(config.AnalyticsConfig{Enabled: true, Anonymize: true})
+		collector.NotifyMailReceived(&domain.Message{MailboxID: "mb-1", From: "alerts@shop.example", To: "box@temp.mail"})
+		require.NoError(t, collector.Flush(t.Context()))
+
+		senders, err := collector.Query(t.Context(), Query{Metric: domain.AnalyticsMessagesBySender, From: *now, To: now.Add(24 * time.Hour)})
+		require.NoError(t, err)
+		assert.Empty(t, senders.Series)
+		byDomain, err := collector.Query(t.Context(), Query{Metric: domain.AnalyticsMessagesByDomain, From: *now, To: now.Add(24 * time.Hour)})
+		require.NoError(t, err)
+		assert.Len(t, byDomain.Series, 1, "其余统计照常记录")
+	})
+
+	t.Run("按保留期限清理", func(t *testing.T) {
+		collector, store, now := newTestCollector(config.AnalyticsConfig{Enabled: true, Retention: 25 * 24 * time.Hour})
+		start := *now
+		for _, offset := range []time.Duration{0, 10 * 24 * time.Hour, 40 * 24 * time.Hour} {
+			*now = start.Add(offset)
+			collector.RecordFeature(FeatureSearch)
+		}
+		require.NoError(t, collector.Flush(t.Context()))
+
+		deleted, err := collector.Prune(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), deleted, "早于 25 天前的时间桶被删除")
+
+		buckets, err := store.ListAnalyticsBuckets(t.Context(), domain.AnalyticsFeatureUsage, start, now.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, buckets, 1)
+		assert.True(t, now.Truncate(time.Hour).Equal(buckets[0].Bucket))
+	})
+
+	t.Run("关闭收集器时不写入任何数据", func(t *testing.T) {
+		collector, store, _ := newTestCollector(config.AnalyticsConfig{Enabled: false})
+		collector.SetConnectionCounter(&fixedConnections{})
+		collector.NotifyMailboxCreated(&domain.Mailbox{ID: "mb-1", Domain: "temp.mail"})
+		collector.NotifyMailReceived(&domain.Message{MailboxID: "mb-1", From: "a@b.example", To: "box@temp.mail"})
+		collector.RecordFeature(FeatureSearch)
+		collector.Sample()
+		require.NoError(t, collector.Flush(t.Context()))
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		collector.Run(ctx)
+		assert.Zero(t, store.writes.Load())
+	})
+
+	t.Run("写入失败时计数保留到下次", func(t *testing.T) {
+		collector, _, now := newTestCollector(config.AnalyticsConfig{Enabled: true})
+		collector.RecordFeature(FeatureSearch)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		collector.store = failingStore{collector.store}
+		require.Error(t, collector.Flush(ctx))
+		collector.store = collector.store.(failingStore).AnalyticsRepository
+		collector.RecordFeature(FeatureSearch)
+		require.NoError(t, collector.Flush(t.Context()))
+
+		report, err := collector.Query(t.Context(), Query{Metric: domain.AnalyticsFeatureUsage, From: *now, To: now.Add(24 * time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), report.Series[0].Total)
+	})
+
+	t.Run("查询参数校验", func(t *testing.T) {
+		collector, _, now := newTestCollector(config.AnalyticsConfig{Enabled: true})
+		_, err := collector.Query(t.Context(), Query{Metric: "mailboxes.deleted"})
+		assert.ErrorIs(t, err, ErrUnknownMetric)
+		_, err = collector.Query(t.Context(), Query{Metric: domain.AnalyticsFeatureUsage, Interval: "week"})
+		assert.ErrorIs(t, err, ErrInvalidInterval)
+		_, err = collector.Query(t.Context(), Query{Metric: domain.AnalyticsFeatureUsage, From: *now, To: now.Add(-time.Hour)})
+		assert.ErrorIs(t, err, ErrInvalidTimeRange)
+		_, err = collector.Query(t.Context(), Query{Metric: domain.AnalyticsFeatureUsage, Interval: IntervalHour, From: now.Add(-40 * 24 * time.Hour)})
+		assert.ErrorIs(t, err, ErrTimeRangeTooLong)
+
+		report, err := collector.Query(t.Context(), Query{Metric: domain.AnalyticsFeatureUsage})
+		require.NoError(t, err)
+		assert.Equal(t, IntervalDay, report.Interval)
+		assert.Equal(t, 30*24*time.Hour, report.To.Sub(report.From), "默认最近 30 天")
+	})
+}
+
+// failingStore 写入总是失败的统计存储
+type failingStore struct {
+	storage.AnalyticsRepository
+}
+
+func (failingStore) MergeAnalyticsBuckets(ctx context.Context, buckets []domain.AnalyticsBucket) error {
+	return context.Canceled
+}
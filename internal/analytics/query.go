@@ -0,0 +1,166 @@
+package analytics
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// 时间序列的粒度
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+)
+
+const (
+	// MaxHourlyRange 按小时查询的最大时间范围
+	MaxHourlyRange = 31 * 24 * time.Hour
+	// MaxDailyRange 按天查询的最大时间范围
+	MaxDailyRange = 400 * 24 * time.Hour
+	// maxSeriesDimensions 每个指标最多返回的维度数（按合计值从高到低）
+	maxSeriesDimensions = 20
+)
+
+var (
+	ErrUnknownMetric    = errors.New("unknown analytics metric")
+	ErrInvalidInterval  = errors.New("invalid analytics interval")
+	ErrInvalidTimeRange = errors.New("invalid analytics time range")
+	ErrTimeRangeTooLong = errors.New("analytics time range too long")
+)
+
+// Query 时间序列查询条件，From/To 为空时默认最近 30 天（按小时为最近 24 小时）
+type Query struct {
+	Metric   string
+	Interval string // hour 或 day，默认 day
+	From     time.Time
+	To       time.Time
+}
+
+// Point 时间序列中的一个点
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value int64     `json:"value"`
+}
+
+// Series 一个维度的时间序列，Total 为范围内的合计（峰值类指标为最大值）
+type Series struct {
+	Dimension string  `json:"dimension"`
+	Total     int64   `json:"total"`
+	Points    []Point `json:"points"`
+}
+
+// Report 时间序列查询结果
+type Report struct {
+	Metric   string    `json:"metric"`
+	Interval string    `json:"interval"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Series   []Series  `json:"series"`
+}
+
+// Query 查询指标的时间序列（只含已写入存储的计数，最多延迟一个写入间隔）
+//
+// 范围按粒度对齐到 UTC 整点/零点，缺少数据的时间点补零；维度按合计值从高到低最多返回 20 个。
+func (c *Collector) Query(ctx context.Context, q Query) (*Report, error) {
+	if !domain.IsAnalyticsMetric(q.Metric) {
+		return nil, ErrUnknownMetric
+	}
+
+	var step, maxRange, defaultRange time.Duration
+	switch q.Interval {
+	case "", IntervalDay:
+		q.Interval, step, maxRange, defaultRange = IntervalDay, 24*time.Hour, MaxDailyRange, 30*24*time.Hour
+	case IntervalHour:
+		step, maxRange, defaultRange = time.Hour, MaxHourlyRange, 24*time.Hour
+	default:
+		return nil, ErrInvalidInterval
+	}
+
+	to := q.To.UTC()
+	if q.To.IsZero() {
+		to = c.now().UTC()
+	}
+	if to.Truncate(step) != to {
+		to = to.Truncate(step).Add(step)
+	}
+	from := q.From.UTC().Truncate(step)
+	if q.From.IsZero() {
+		from = to.Add(-defaultRange)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidTimeRange
+	}
+	if to.Sub(from) > maxRange {
+		return nil, ErrTimeRangeTooLong
+	}
+
+	buckets, err := c.store.ListAnalyticsBuckets(ctx, q.Metric, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	peak := domain.IsPeakAnalyticsMetric(q.Metric)
+	values := make(map[string]map[time.Time]int64)
+	totals := make(map[string]int64)
+	for _, bucket := range buckets {
+		at := bucket.Bucket.UTC().Truncate(step)
+		if values[bucket.Dimension] == nil {
+			values[bucket.Dimension] = make(map[time.Time]int64)
+		}
+		if peak {
+			values[bucket.Dimension][at] = max(values[bucket.Dimension][at], bucket.Value)
+			totals[bucket.Dimension] = max(totals[bucket.Dimension], bucket.Value)
+		} else {
+			values[bucket.Dimension][at] += bucket.Value
+			totals[bucket.Dimension] += bucket.Value
+		}
+	}
+
+	dimensions := make([]string, 0, len(values))
+	for dimension := range values {
+		dimensions = append(dimensions, dimension)
+	}
+	sort.Slice(dimensions, func(i, j int) bool {
+		if totals[dimensions[i]] != totals[dimensions[j]] {
+			return totals[dimensions[i]] > totals[dimensions[j]]
+		}
+		return dimensions[i] < dimensions[j]
+	})
+	if len(dimensions) > maxSeriesDimensions {
+		dimensions = dimensions[:maxSeriesDimensions]
+	}
+
+	report := &Report{Metric: q.Metric, Interval: q.Interval, From: from, To: to, Series: make([]Series, 0, len(dimensions))}
+	for _, dimension := range dimensions {
+		series := Series{Dimension: dimension, Total: totals[dimension], Points: make([]Point, 0, int(to.Sub(from)/step))}
+		for at := from; at.Before(to); at = at.Add(step) {
+			series.Points = append(series.Points, Point{Time: at, Value: values[dimension][at]})
+		}
+		report.Series = append(report.Series, series)
+	}
+	return report, nil
+}
+
+// WriteCSV 以 CSV（time,metric,dimension,value）逐行写出查询结果
+func WriteCSV(w io.Writer, report *Report) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"time", "metric", "dimension", "value"}); err != nil {
+		return err
+	}
+	for _, series := range report.Series {
+		for _, point := range series.Points {
+			record := []string{point.Time.Format(time.RFC3339), report.Metric, series.Dimension, strconv.FormatInt(point.Value, 10)}
+			if err := out.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
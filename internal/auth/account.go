@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"tempmail/backend/internal/domain"
+)
+
+var (
+	// ErrIncorrectPassword 当前密码错误（修改密码、删除账户时确认身份）
+	ErrIncorrectPassword = errors.New("incorrect password")
+	// ErrInvalidUsername 用户名格式无效
+	ErrInvalidUsername = errors.New("username must be 3-32 characters of letters, digits, '.', '_' or '-'")
+)
+
+// usernameRegex 用户名：3-32 位字母、数字、点、下划线或连字符（不含 @，避免与邮箱登录混淆）
+var usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]{3,32}$`)
+
+// ValidateUsername 验证用户名格式
+func ValidateUsername(username string) error {
+	if !usernameRegex.MatchString(username) {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
+// ChangeUsername 修改用户名（不区分大小写唯一）
+func (s *Service) ChangeUsername(ctx context.Context, userID, username string) (*domain.User, error) {
+	username = strings.TrimSpace(username)
+	if err := ValidateUsername(username); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Username == username {
+		return user, nil
+	}
+	if existing, err := s.userRepo.GetUserByUsername(ctx, strings.ToLower(username)); err == nil && existing != nil && existing.ID != user.ID {
+		return nil, ErrUsernameExists
+	}
+
+	user.Username = username
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	return user, nil
+}
+
+// VerifyPassword 确认当前用户的密码（如删除账户前），错误计入登录失败次数
+func (s *Service) VerifyPassword(ctx context.Context, userID, password, ip string) (*domain.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if err := s.verifyPassword(ctx, user, password, ip); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// verifyPassword 校验密码，与登录共用锁定和失败计数（持有访问令牌也不能无限次尝试密码）
+func (s *Service) verifyPassword(ctx context.Context, user *domain.User, password, ip string) error {
+	if s.guard != nil {
+		if err := s.checkLock(ctx, user); err != nil {
+			return err
+		}
+		s.guard.delay(ctx, user.ID)
+	}
+	if user.PasswordHash != "" && CheckPassword(password, user.PasswordHash) {
+		return nil
+	}
+	if err := s.loginFailed(ctx, user, ip); !errors.Is(err, ErrInvalidCredentials) {
+		return err
+	}
+	return ErrIncorrectPassword
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+
+	"tempmail/backend/internal/auth/jwt"
+)
+
+// ResetAppPassword 重置应用专用密码：递增用户的应用密码代数，之前分发的应用密码立即失效
+func (s *Service) ResetAppPassword(ctx context.Context, userID string) (int, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, ErrUserNotFound
+	}
+	user.AppPasswordGeneration++
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return 0, err
+	}
+	return user.AppPasswordGeneration, nil
+}
+
+// AppPasswordVerifier 按用户当前的应用密码代数校验应用专用密码（IMAP 登录使用）
+type AppPasswordVerifier struct {
+	users  UserRepository
+	tokens *jwt.Manager
+}
+
+// NewAppPasswordVerifier 创建应用专用密码校验
+func NewAppPasswordVerifier(users UserRepository, tokens *jwt.Manager) *AppPasswordVerifier {
+	return &AppPasswordVerifier{users: users, tokens: tokens}
+}
+
+// VerifyAppPassword 校验应用专用密码（用户不存在或已禁用时拒绝）
+func (v *AppPasswordVerifier) VerifyAppPassword(ctx context.Context, userID, password string) bool {
+	user, err := v.users.GetUserByID(ctx, userID)
+	if err != nil || user == nil || !user.IsActive {
+		return false
+	}
+	return v.tokens.VerifyAppPassword(user.ID, user.AppPasswordGeneration, password)
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestAppPasswordGeneration(t *testing.T) {
+	newFixture := func(t *testing.T) (*Service, *SessionService, *AppPasswordVerifier, *memory.Store, *domain.User) {
+		t.Helper()
+		store := memory.NewStore(time.Hour)
+		service := NewService(store)
+		user, err := service.Register(t.Context(), RegisterInput{Email: "alice@example.com", Password: testPassword, Username: "alice"})
+		require.NoError(t, err)
+		tokens := jwt.NewManager("test-secret-0123456789abcdefghijklmnop", "test", 15*time.Minute, 24*time.Hour)
+		return service, NewSessionService(store, store, tokens), NewAppPasswordVerifier(store, tokens), store, user
+	}
+	tokens := jwt.NewManager("test-secret-0123456789abcdefghijklmnop", "test", 15*time.Minute, 24*time.Hour)
+	original := func(user *domain.User) string { return tokens.AppPassword(user.ID, 0) }
+
+	t.Run("重置后旧密码失效，新密码可用", func(t *testing.T) {
+		service, _, verifier, _, user := newFixture(t)
+		require.True(t, verifier.VerifyAppPassword(t.Context(), user.ID, original(user)))
+
+		generation, err := service.ResetAppPassword(t.Context(), user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, generation)
+		assert.False(t, verifier.VerifyAppPassword(t.Context(), user.ID, original(user)))
+		assert.True(t, verifier.VerifyAppPassword(t.Context(), user.ID, tokens.AppPassword(user.ID, generation)))
+
+		_, err = service.ResetAppPassword(t.Context(), "missing")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("修改密码后旧应用密码失效", func(t *testing.T) {
+		service, _, verifier, _, user := newFixture(t)
+		require.NoError(t, service.ChangePassword(t.Context(), user.ID, testPassword, "another-password", ""))
+		assert.False(t, verifier.VerifyAppPassword(t.Context(), user.ID, original(user)))
+	})
+
+	t.Run("注销全部会话后旧应用密码失效", func(t *testing.T) {
+		_, sessions, verifier, _, user := newFixture(t)
+		require.NoError(t, sessions.RevokeAll(t.Context(), user.ID, ""))
+		assert.False(t, verifier.VerifyAppPassword(t.Context(), user.ID, original(user)))
+		assert.NoError(t, sessions.RevokeAll(t.Context(), "deleted-user", ""), "账户已删除时跳过")
+	})
+
+	t.Run("禁用的用户被拒绝", func(t *testing.T) {
+		_, _, verifier, store, user := newFixture(t)
+		user.IsActive = false
+		require.NoError(t, store.UpdateUser(t.Context(), user))
+		assert.False(t, verifier.VerifyAppPassword(t.Context(), user.ID, original(user)))
+	})
+}
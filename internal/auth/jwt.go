@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"context"
+
 	"tempmail/backend/internal/auth/jwt"
 	"tempmail/backend/internal/config"
 	"tempmail/backend/internal/domain"
@@ -32,8 +34,8 @@ type Claims struct {
 }
 
 // GenerateTokens 生成令牌对
-func (j *JWTManager) GenerateTokens(userID string, role string) (*TokenResponse, error) {
-	tokenPair, err := j.manager.GenerateTokenPair(userID, "", role)
+func (j *JWTManager) GenerateTokens(ctx context.Context, userID string, role string) (*TokenResponse, error) {
+	tokenPair, err := j.manager.GenerateTokenPair(ctx, userID, "", role)
 	if err != nil {
 		return nil, err
 	}
@@ -60,15 +62,15 @@ func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 }
 
 // RefreshToken 刷新令牌
-func (j *JWTManager) RefreshToken(refreshToken string) (*TokenResponse, error) {
+func (j *JWTManager) RefreshToken(ctx context.Context, refreshToken string) (*TokenResponse, error) {
 	// 先验证刷新令牌
-	claims, err := j.manager.ValidateToken(refreshToken)
+	claims, err := j.manager.ValidateRefreshToken(refreshToken)
 	if err != nil {
 		return nil, err
 	}
 
 	// 生成新的令牌对
-	tokenPair, err := j.manager.GenerateTokenPair(claims.UserID, claims.Email, claims.Tier)
+	tokenPair, err := j.manager.GenerateTokenPair(ctx, claims.UserID, claims.Email, claims.Tier)
 	if err != nil {
 		return nil, err
 	}
@@ -106,20 +108,20 @@ type AuthResponse struct {
 }
 
 // Register 用户注册
-func (a *AuthService) Register(req *domain.RegisterRequest) (*AuthResponse, error) {
+func (a *AuthService) Register(ctx context.Context, req *domain.RegisterRequest) (*AuthResponse, error) {
 	input := RegisterInput{
 		Email:    req.Email,
 		Password: req.Password,
 		Username: req.Username,
 	}
 
-	user, err := a.service.Register(input)
+	user, err := a.service.Register(ctx, input)
 	if err != nil {
 		return nil, err
 	}
 
 	// 生成令牌
-	tokens, err := a.jwtManager.GenerateTokens(user.ID, string(user.Role))
+	tokens, err := a.jwtManager.GenerateTokens(ctx, user.ID, string(user.Role))
 	if err != nil {
 		return nil, err
 	}
@@ -134,19 +136,22 @@ func (a *AuthService) Register(req *domain.RegisterRequest) (*AuthResponse, erro
 }
 
 // Login 用户登录
-func (a *AuthService) Login(req *domain.LoginRequest) (*AuthResponse, error) {
+func (a *AuthService) Login(ctx context.Context, req *domain.LoginRequest) (*AuthResponse, error) {
 	input := LoginInput{
 		Identifier: req.Username, // 可以是用户名或邮箱
 		Password:   req.Password,
 	}
 
-	user, err := a.service.Login(input)
+	user, err := a.service.Login(ctx, input)
 	if err != nil {
 		return nil, err
 	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorRequired
+	}
 
 	// 生成令牌
-	tokens, err := a.jwtManager.GenerateTokens(user.ID, string(user.Role))
+	tokens, err := a.jwtManager.GenerateTokens(ctx, user.ID, string(user.Role))
 	if err != nil {
 		return nil, err
 	}
@@ -161,16 +166,16 @@ func (a *AuthService) Login(req *domain.LoginRequest) (*AuthResponse, error) {
 }
 
 // RefreshToken 刷新令牌
-func (a *AuthService) RefreshToken(req *domain.RefreshTokenRequest) (*TokenResponse, error) {
-	return a.jwtManager.RefreshToken(req.RefreshToken)
+func (a *AuthService) RefreshToken(ctx context.Context, req *domain.RefreshTokenRequest) (*TokenResponse, error) {
+	return a.jwtManager.RefreshToken(ctx, req.RefreshToken)
 }
 
 // GetUserByID 根据ID获取用户
-func (a *AuthService) GetUserByID(userID string) (*domain.User, error) {
-	return a.service.GetUserByID(userID)
+func (a *AuthService) GetUserByID(ctx context.Context, userID string) (*domain.User, error) {
+	return a.service.GetUserByID(ctx, userID)
 }
 
 // ChangePassword 修改密码
-func (a *AuthService) ChangePassword(req *domain.ChangePasswordRequest) error {
-	return a.service.ChangePassword(req.UserID, req.OldPassword, req.NewPassword)
+func (a *AuthService) ChangePassword(ctx context.Context, req *domain.ChangePasswordRequest) error {
+	return a.service.ChangePassword(ctx, req.UserID, req.OldPassword, req.NewPassword, "")
 }
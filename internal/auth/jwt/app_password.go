@@ -0,0 +1,59 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"strconv"
+	"strings"
+)
+
+// appPasswordLength 应用专用密码的字符数（不含分隔符，80 位）
+const appPasswordLength = 16
+
+var appPasswordEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// AppPassword 由当前签名密钥和用户的应用密码代数派生应用专用密码（供 IMAP 等不支持令牌登录的客户端使用）
+//
+// 密码不落库：同一密钥和代数下结果固定，密钥轮换后旧密码在旧密钥的验证期内仍然有效；
+// 代数递增（重置应用密码、修改密码、注销全部会话）后旧密码立即失效。
+func (m *Manager) AppPassword(userID string, generation int) string {
+	current, _ := m.Keys()
+	raw := deriveAppPassword(current.Secret, userID, generation)
+	groups := make([]string, 0, appPasswordLength/4)
+	for i := 0; i < len(raw); i += 4 {
+		groups = append(groups, raw[i:i+4])
+	}
+	return strings.Join(groups, "-")
+}
+
+// VerifyAppPassword 按用户当前的应用密码代数校验应用专用密码（忽略大小写、空格和连字符）
+func (m *Manager) VerifyAppPassword(userID string, generation int, password string) bool {
+	if userID == "" {
+		return false
+	}
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(password))
+	current, previous := m.Keys()
+	keys := []Key{current}
+	if previous != nil {
+		keys = append(keys, *previous)
+	}
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(deriveAppPassword(key.Secret, userID, generation)), []byte(normalized)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// deriveAppPassword 第 0 代沿用不含代数的输入，已分发的应用密码在首次递增前保持有效
+func deriveAppPassword(secret, userID string, generation int) string {
+	input := "app-password:" + userID
+	if generation > 0 {
+		input += ":" + strconv.Itoa(generation)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return appPasswordEncoding.EncodeToString(mac.Sum(nil))[:appPasswordLength]
+}
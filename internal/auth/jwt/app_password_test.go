@@ -0,0 +1,47 @@
+package jwt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_AppPassword(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	m := NewManager(oldSecret, "tempmail", 15*time.Minute, 7*24*time.Hour)
+	m.now = func() time.Time { return now }
+
+	password := m.AppPassword("user-1", 0)
+	assert.Regexp(t, `^[a-z2-7]{4}(-[a-z2-7]{4}){3}$`, password)
+	assert.Equal(t, password, m.AppPassword("user-1", 0), "同一密钥下结果固定")
+	assert.NotEqual(t, password, m.AppPassword("user-2", 0))
+
+	t.Run("忽略大小写和分隔符", func(t *testing.T) {
+		assert.True(t, m.VerifyAppPassword("user-1", 0, password))
+		assert.True(t, m.VerifyAppPassword("user-1", 0, strings.ToUpper(strings.ReplaceAll(password, "-", " "))))
+		assert.False(t, m.VerifyAppPassword("user-2", 0, password))
+		assert.False(t, m.VerifyAppPassword("", 0, password))
+		assert.False(t, m.VerifyAppPassword("user-1", 0, ""))
+	})
+
+	t.Run("代数递增后旧密码失效", func(t *testing.T) {
+		next := m.AppPassword("user-1", 1)
+		assert.NotEqual(t, password, next)
+		assert.True(t, m.VerifyAppPassword("user-1", 1, next))
+		assert.False(t, m.VerifyAppPassword("user-1", 1, password))
+		assert.False(t, m.VerifyAppPassword("user-1", 0, next))
+	})
+
+	t.Run("密钥轮换后旧密码在验证期内有效", func(t *testing.T) {
+		require.NoError(t, m.Rotate(Key{ID: "k2", Secret: newSecret}))
+		assert.NotEqual(t, password, m.AppPassword("user-1", 0))
+		assert.True(t, m.VerifyAppPassword("user-1", 0, password))
+		assert.True(t, m.VerifyAppPassword("user-1", 0, m.AppPassword("user-1", 0)))
+
+		now = now.Add(8 * 24 * time.Hour)
+		assert.False(t, m.VerifyAppPassword("user-1", 0, password))
+	})
+}
@@ -0,0 +1,51 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// PurposeTwoFactor 两步验证质询令牌的用途
+const PurposeTwoFactor = "2fa"
+
+// GenerateChallengeToken 签发专用短期令牌（如密码验证通过后等待输入两步验证码）
+//
+// 令牌带 purpose 声明，ValidateToken 拒绝此类令牌，不能当作访问令牌或刷新令牌使用。
+func (m *Manager) GenerateChallengeToken(userID, purpose string, ttl time.Duration) (string, error) {
+	now := m.now()
+	token, err := m.sign(Claims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign challenge token: %w", err)
+	}
+	return token, nil
+}
+
+// ValidateChallengeToken 验证专用令牌的签名、有效期和用途，返回用户 ID
+func (m *Manager) ValidateChallengeToken(tokenString, purpose string) (string, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.verificationKeys, jwt.WithTimeFunc(m.now))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return "", ErrExpiredToken
+		}
+		return "", ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid || purpose == "" || claims.Purpose != purpose || claims.UserID == "" {
+		return "", ErrInvalidToken
+	}
+	return claims.UserID, nil
+}
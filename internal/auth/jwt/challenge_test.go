@@ -0,0 +1,47 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_ChallengeToken(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	m := NewManager(oldSecret, "tempmail", 15*time.Minute, 7*24*time.Hour)
+	m.now = func() time.Time { return now }
+
+	token, err := m.GenerateChallengeToken("user-1", PurposeTwoFactor, 5*time.Minute)
+	require.NoError(t, err)
+
+	t.Run("用途匹配时返回用户ID", func(t *testing.T) {
+		userID, err := m.ValidateChallengeToken(token, PurposeTwoFactor)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+	})
+
+	t.Run("不能当作访问令牌或刷新令牌使用", func(t *testing.T) {
+		_, err := m.ValidateToken(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		_, err = m.RefreshAccessToken(t.Context(), token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("访问令牌不能当作质询令牌使用", func(t *testing.T) {
+		pair, err := m.GenerateTokenPair(t.Context(), "user-1", "u@example.com", "free")
+		require.NoError(t, err)
+		_, err = m.ValidateChallengeToken(pair.AccessToken, PurposeTwoFactor)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		_, err = m.ValidateChallengeToken(token, "other")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("过期后拒绝", func(t *testing.T) {
+		m.now = func() time.Time { return now.Add(6 * time.Minute) }
+		defer func() { m.now = func() time.Time { return now } }()
+		_, err := m.ValidateChallengeToken(token, PurposeTwoFactor)
+		assert.ErrorIs(t, err, ErrExpiredToken)
+	})
+}
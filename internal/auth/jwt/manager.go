@@ -1,11 +1,16 @@
 package jwt
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 var (
@@ -13,101 +18,321 @@ var (
 	ErrInvalidToken = errors.New("invalid token")
 	// ErrExpiredToken 令牌已过期
 	ErrExpiredToken = errors.New("token expired")
+	// ErrWeakSecret 签名密钥过短
+	ErrWeakSecret = errors.New("signing secret must be at least 32 characters")
 )
 
+// MinSecretLength 签名密钥最小长度
+const MinSecretLength = 32
+
 // Claims JWT 自定义声明
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Tier   string `json:"tier"`
+	UserID       string     `json:"user_id"`
+	Email        string     `json:"email"`
+	Tier         string     `json:"tier"`
+	Orgs         []OrgClaim `json:"orgs,omitempty"`    // 组织成员关系
+	OrgVersion   string     `json:"org_ver,omitempty"` // 成员关系版本戳，变化后令牌中的组织声明视为过期
+	Purpose      string     `json:"purpose,omitempty"` // 专用令牌的用途（如刷新令牌、两步验证质询），访问令牌为空
+	SessionID    string     `json:"sid,omitempty"`     // 登录会话ID，同一会话轮换出的令牌相同
+	Impersonator string     `json:"imp,omitempty"`     // 代为登录的管理员ID（只读的代登录令牌）
 	jwt.RegisteredClaims
 }
 
+// OrgClaim 组织成员关系声明
+type OrgClaim struct {
+	OrgID string `json:"org_id"`
+	Role  string `json:"role"`
+}
+
+// Blacklist 已吊销的访问令牌（按 jti，注销或撤销会话时写入）
+type Blacklist interface {
+	IsBlacklisted(ctx context.Context, jti string) (bool, error)
+}
+
+// OrgResolver 查询用户当前的组织成员关系及版本戳
+type OrgResolver func(ctx context.Context, userID string) (orgs []OrgClaim, version string)
+
+// PurposeRefresh 刷新令牌的用途（只能用于 /refresh，不能当作访问令牌）
+const PurposeRefresh = "refresh"
+
 // TokenPair 访问令牌和刷新令牌对
 type TokenPair struct {
 	AccessToken  string `json:"accessToken"`
 	RefreshToken string `json:"refreshToken"`
 	ExpiresIn    int64  `json:"expiresIn"` // 秒
+
+	SessionID        string    `json:"-"`
+	AccessID         string    `json:"-"` // 访问令牌 jti
+	AccessExpiresAt  time.Time `json:"-"`
+	RefreshID        string    `json:"-"` // 刷新令牌 jti
+	RefreshExpiresAt time.Time `json:"-"`
+}
+
+// Key 签名密钥
+type Key struct {
+	ID       string    // 密钥ID（令牌 kid 头）
+	Secret   string    // HMAC 密钥
+	RetireAt time.Time // 停止验证的时间（仅旧密钥，零值表示不过期）
+}
+
+// retired 判断旧密钥是否已到期
+func (k *Key) retired(now time.Time) bool {
+	return !k.RetireAt.IsZero() && !now.Before(k.RetireAt)
+}
+
+// KeyID 由密钥派生的默认密钥ID（各实例对同一密钥得到相同ID）
+func KeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:4])
 }
 
 // Manager JWT 管理器
+//
+// 新令牌用当前密钥签名并写入 kid 头；验证时优先使用 kid 匹配的密钥，再依次尝试其余密钥，
+// 轮换后旧密钥签发的令牌在刷新令牌有效期内仍然有效。
 type Manager struct {
-	secret        []byte
+	mu       sync.RWMutex
+	current  Key
+	previous *Key
+
 	issuer        string
 	accessExpiry  time.Duration
 	refreshExpiry time.Duration
+	orgResolver   OrgResolver // 可选：签发令牌时附带组织成员关系
+	blacklist     Blacklist   // 可选：Authenticate 拒绝已吊销的访问令牌
+	now           func() time.Time
 }
 
 // NewManager 创建 JWT 管理器
 func NewManager(secret, issuer string, accessExpiry, refreshExpiry time.Duration) *Manager {
 	return &Manager{
-		secret:        []byte(secret),
+		current:       Key{ID: KeyID(secret), Secret: secret},
 		issuer:        issuer,
 		accessExpiry:  accessExpiry,
 		refreshExpiry: refreshExpiry,
+		now:           time.Now,
+	}
+}
+
+// SetKeys 设置当前密钥和旧密钥（启动配置或从共享配置同步），密钥ID为空时由密钥派生
+func (m *Manager) SetKeys(current Key, previous *Key) error {
+	if len(current.Secret) < MinSecretLength {
+		return ErrWeakSecret
 	}
+	if current.ID == "" {
+		current.ID = KeyID(current.Secret)
+	}
+	current.RetireAt = time.Time{}
+	if previous != nil {
+		if len(previous.Secret) < MinSecretLength {
+			return ErrWeakSecret
+		}
+		copied := *previous
+		if copied.ID == "" {
+			copied.ID = KeyID(copied.Secret)
+		}
+		previous = &copied
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.current, m.previous = current, previous
+	return nil
+}
+
+// Rotate 切换到新密钥：当前密钥转为旧密钥，在刷新令牌有效期后停止验证
+func (m *Manager) Rotate(next Key) error {
+	if len(next.Secret) < MinSecretLength {
+		return ErrWeakSecret
+	}
+	if next.ID == "" {
+		next.ID = KeyID(next.Secret)
+	}
+	next.RetireAt = time.Time{}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if next.ID == m.current.ID {
+		return fmt.Errorf("key id %q already in use", next.ID)
+	}
+	previous := m.current
+	previous.RetireAt = m.now().Add(m.refreshExpiry)
+	m.current, m.previous = next, &previous
+	return nil
 }
 
-// GenerateTokenPair 生成访问令牌和刷新令牌对
-func (m *Manager) GenerateTokenPair(userID, email, tier string) (*TokenPair, error) {
-	now := time.Now()
+// Keys 返回当前密钥和仍在验证期内的旧密钥（旧密钥到期后自动丢弃）
+func (m *Manager) Keys() (Key, *Key) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.previous != nil && m.previous.retired(m.now()) {
+		m.previous = nil
+	}
+	if m.previous == nil {
+		return m.current, nil
+	}
+	previous := *m.previous
+	return m.current, &previous
+}
+
+// sign 用当前密钥签名并写入 kid 头
+func (m *Manager) sign(claims Claims) (string, error) {
+	m.mu.RLock()
+	key := m.current
+	m.mu.RUnlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString([]byte(key.Secret))
+}
+
+// verificationKeys 验证令牌可用的密钥：kid 匹配的密钥在前
+func (m *Manager) verificationKeys(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	current, previous := m.Keys()
+	keys := []Key{current}
+	if previous != nil {
+		keys = append(keys, *previous)
+		if kid, _ := token.Header["kid"].(string); kid != "" && kid == previous.ID {
+			keys[0], keys[1] = keys[1], keys[0]
+		}
+	}
+
+	set := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(keys))}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, []byte(key.Secret))
+	}
+	return set, nil
+}
+
+// SetOrgResolver 设置组织成员关系查询（签发和刷新访问令牌时写入声明）
+func (m *Manager) SetOrgResolver(resolver OrgResolver) {
+	m.orgResolver = resolver
+}
+
+// resolveOrgs 查询用户当前的组织声明
+func (m *Manager) resolveOrgs(ctx context.Context, userID string) ([]OrgClaim, string) {
+	if m.orgResolver == nil {
+		return nil, ""
+	}
+	return m.orgResolver(ctx, userID)
+}
+
+// OrgClaimsStale 判断令牌中的组织声明是否已过期（成员关系在签发后发生变化）
+func (m *Manager) OrgClaimsStale(ctx context.Context, claims *Claims) bool {
+	if m.orgResolver == nil {
+		return false
+	}
+	_, version := m.orgResolver(ctx, claims.UserID)
+	return version != claims.OrgVersion
+}
+
+// GenerateTokenPair 为新的登录会话生成访问令牌和刷新令牌对
+func (m *Manager) GenerateTokenPair(ctx context.Context, userID, email, tier string) (*TokenPair, error) {
+	return m.GenerateSessionTokenPair(ctx, uuid.NewString(), userID, email, tier)
+}
+
+// GenerateSessionTokenPair 为指定会话生成令牌对（刷新时轮换），两个令牌都带新的 jti
+func (m *Manager) GenerateSessionTokenPair(ctx context.Context, sessionID, userID, email, tier string) (*TokenPair, error) {
+	now := m.now()
+	orgs, orgVersion := m.resolveOrgs(ctx, userID)
+	pair := &TokenPair{
+		ExpiresIn:        int64(m.accessExpiry.Seconds()),
+		SessionID:        sessionID,
+		AccessID:         uuid.NewString(),
+		AccessExpiresAt:  now.Add(m.accessExpiry),
+		RefreshID:        uuid.NewString(),
+		RefreshExpiresAt: now.Add(m.refreshExpiry),
+	}
 
 	// 生成访问令牌
 	accessClaims := Claims{
-		UserID: userID,
-		Email:  email,
-		Tier:   tier,
+		UserID:     userID,
+		Email:      email,
+		Tier:       tier,
+		Orgs:       orgs,
+		OrgVersion: orgVersion,
+		SessionID:  sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        pair.AccessID,
 			Issuer:    m.issuer,
 			Subject:   userID,
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessExpiry)),
+			ExpiresAt: jwt.NewNumericDate(pair.AccessExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString(m.secret)
+	var err error
+	pair.AccessToken, err = m.sign(accessClaims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign access token: %w", err)
 	}
 
 	// 生成刷新令牌
 	refreshClaims := Claims{
-		UserID: userID,
-		Email:  email,
-		Tier:   tier,
+		UserID:    userID,
+		Email:     email,
+		Tier:      tier,
+		Purpose:   PurposeRefresh,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        pair.RefreshID,
 			Issuer:    m.issuer,
 			Subject:   userID,
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.refreshExpiry)),
+			ExpiresAt: jwt.NewNumericDate(pair.RefreshExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString(m.secret)
+	pair.RefreshToken, err = m.sign(refreshClaims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign refresh token: %w", err)
 	}
 
-	return &TokenPair{
-		AccessToken:  accessTokenString,
-		RefreshToken: refreshTokenString,
-		ExpiresIn:    int64(m.accessExpiry.Seconds()),
-	}, nil
+	return pair, nil
 }
 
-// ValidateToken 验证令牌并返回声明
+// ValidateToken 验证访问令牌并返回声明（刷新令牌和专用令牌被拒绝）
 func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// 验证签名算法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return m.secret, nil
-	})
+	return m.validate(tokenString, "")
+}
+
+// SetBlacklist 设置令牌黑名单（启动时设置），Authenticate 拒绝注销或撤销会话后仍在有效期内的访问令牌
+func (m *Manager) SetBlacklist(blacklist Blacklist) {
+	m.blacklist = blacklist
+}
+
+// Authenticate 验证访问令牌并检查黑名单，已吊销的令牌返回 ErrInvalidToken
+//
+// 所有以访问令牌认证的入口（HTTP、邮箱令牌接口、gRPC、WebSocket）都应使用此方法而不是 ValidateToken；
+// 黑名单查询失败时放行（访问令牌有效期较短）。
+func (m *Manager) Authenticate(ctx context.Context, tokenString string) (*Claims, error) {
+	claims, err := m.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if m.blacklist == nil || claims.ID == "" {
+		return claims, nil
+	}
+	if revoked, err := m.blacklist.IsBlacklisted(ctx, claims.ID); err == nil && revoked {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// ValidateRefreshToken 验证刷新令牌并返回声明
+func (m *Manager) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return m.validate(tokenString, PurposeRefresh)
+}
+
+// validate 验证签名、有效期和用途
+func (m *Manager) validate(tokenString, purpose string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.verificationKeys, jwt.WithTimeFunc(m.now))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -117,27 +342,32 @@ func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
 	}
 
 	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
+	if !ok || !token.Valid || claims.Purpose != purpose {
 		return nil, ErrInvalidToken
 	}
 
 	return claims, nil
 }
 
-// RefreshAccessToken 使用刷新令牌生成新的访问令牌
-func (m *Manager) RefreshAccessToken(refreshToken string) (string, error) {
-	claims, err := m.ValidateToken(refreshToken)
+// RefreshAccessToken 使用刷新令牌生成新的访问令牌（不轮换刷新令牌，HTTP 接口使用 auth.SessionService）
+func (m *Manager) RefreshAccessToken(ctx context.Context, refreshToken string) (string, error) {
+	claims, err := m.ValidateRefreshToken(refreshToken)
 	if err != nil {
 		return "", err
 	}
 
-	// 生成新的访问令牌
-	now := time.Now()
+	// 生成新的访问令牌（重新查询组织成员关系）
+	now := m.now()
+	orgs, orgVersion := m.resolveOrgs(ctx, claims.UserID)
 	newClaims := Claims{
-		UserID: claims.UserID,
-		Email:  claims.Email,
-		Tier:   claims.Tier,
+		UserID:     claims.UserID,
+		Email:      claims.Email,
+		Tier:       claims.Tier,
+		Orgs:       orgs,
+		OrgVersion: orgVersion,
+		SessionID:  claims.SessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			Issuer:    m.issuer,
 			Subject:   claims.UserID,
 			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessExpiry)),
@@ -146,8 +376,7 @@ func (m *Manager) RefreshAccessToken(refreshToken string) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims)
-	tokenString, err := token.SignedString(m.secret)
+	tokenString, err := m.sign(newClaims)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
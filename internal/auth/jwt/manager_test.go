@@ -0,0 +1,143 @@
+package jwt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	oldSecret = "old-secret-0123456789abcdefghijklmnop"
+	newSecret = "new-secret-0123456789abcdefghijklmnop"
+)
+
+// tokenKeyID 读取令牌的 kid 头
+func tokenKeyID(t *testing.T, tokenString string) string {
+	t.Helper()
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, &Claims{})
+	require.NoError(t, err)
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
+
+func TestManager_Rotate(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	m := NewManager(oldSecret, "tempmail", 15*time.Minute, 7*24*time.Hour)
+	m.now = func() time.Time { return now }
+
+	before, err := m.GenerateTokenPair(t.Context(), "user-1", "u@example.com", "free")
+	require.NoError(t, err)
+	assert.Equal(t, KeyID(oldSecret), tokenKeyID(t, before.AccessToken))
+
+	require.NoError(t, m.Rotate(Key{ID: "k2", Secret: newSecret}))
+
+	t.Run("新令牌使用新密钥ID", func(t *testing.T) {
+		pair, err := m.GenerateTokenPair(t.Context(), "user-1", "u@example.com", "free")
+		require.NoError(t, err)
+		assert.Equal(t, "k2", tokenKeyID(t, pair.AccessToken))
+		assert.Equal(t, "k2", tokenKeyID(t, pair.RefreshToken))
+
+		claims, err := m.ValidateToken(pair.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", claims.UserID)
+	})
+
+	t.Run("重叠期内旧密钥签发的令牌仍有效", func(t *testing.T) {
+		claims, err := m.ValidateToken(before.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", claims.UserID)
+
+		access, err := m.RefreshAccessToken(t.Context(), before.RefreshToken)
+		require.NoError(t, err)
+		assert.Equal(t, "k2", tokenKeyID(t, access), "刷新得到的令牌使用新密钥")
+	})
+
+	t.Run("kid 缺失或不匹配时依次尝试所有密钥", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: "user-2"})
+		token.Header["kid"] = "unknown"
+		signed, err := token.SignedString([]byte(oldSecret))
+		require.NoError(t, err)
+		_, err = m.ValidateToken(signed)
+		assert.NoError(t, err)
+	})
+
+	t.Run("旧密钥到期后拒绝", func(t *testing.T) {
+		_, previous := m.Keys()
+		require.NotNil(t, previous)
+		assert.Equal(t, now.Add(7*24*time.Hour), previous.RetireAt, "旧密钥在刷新令牌有效期后失效")
+
+		// 旧密钥签发、本身尚未过期的令牌
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+			UserID:           "user-1",
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(now.Add(30 * 24 * time.Hour))},
+		})
+		token.Header["kid"] = KeyID(oldSecret)
+		stale, err := token.SignedString([]byte(oldSecret))
+		require.NoError(t, err)
+
+		now = previous.RetireAt.Add(-time.Second)
+		_, err = m.ValidateToken(stale)
+		assert.NoError(t, err)
+
+		now = previous.RetireAt
+		_, err = m.ValidateToken(stale)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+
+		_, previous = m.Keys()
+		assert.Nil(t, previous, "到期的旧密钥被丢弃")
+	})
+}
+
+func TestManager_SetKeys(t *testing.T) {
+	m := NewManager(oldSecret, "tempmail", time.Minute, time.Hour)
+
+	assert.ErrorIs(t, m.SetKeys(Key{Secret: "short"}, nil), ErrWeakSecret)
+	assert.ErrorIs(t, m.SetKeys(Key{Secret: newSecret}, &Key{Secret: "short"}), ErrWeakSecret)
+	assert.ErrorIs(t, m.Rotate(Key{Secret: strings.Repeat("x", MinSecretLength-1)}), ErrWeakSecret)
+
+	require.NoError(t, m.SetKeys(Key{Secret: newSecret}, nil))
+	current, previous := m.Keys()
+	assert.Equal(t, KeyID(newSecret), current.ID, "未指定ID时由密钥派生")
+	assert.Nil(t, previous)
+
+	assert.Error(t, m.Rotate(Key{Secret: newSecret}), "不能轮换到相同的密钥")
+}
+
+func TestManager_SessionTokens(t *testing.T) {
+	m := NewManager(oldSecret, "tempmail", 15*time.Minute, 7*24*time.Hour)
+
+	pair, err := m.GenerateTokenPair(t.Context(), "user-1", "u@example.com", "free")
+	require.NoError(t, err)
+	require.NotEmpty(t, pair.SessionID)
+
+	t.Run("访问令牌和刷新令牌带会话ID和不同的 jti", func(t *testing.T) {
+		access, err := m.ValidateToken(pair.AccessToken)
+		require.NoError(t, err)
+		refresh, err := m.ValidateRefreshToken(pair.RefreshToken)
+		require.NoError(t, err)
+
+		assert.Equal(t, pair.SessionID, access.SessionID)
+		assert.Equal(t, pair.SessionID, refresh.SessionID)
+		assert.Equal(t, pair.AccessID, access.ID)
+		assert.Equal(t, pair.RefreshID, refresh.ID)
+		assert.NotEqual(t, access.ID, refresh.ID)
+	})
+
+	t.Run("刷新令牌不能当作访问令牌，反之亦然", func(t *testing.T) {
+		_, err := m.ValidateToken(pair.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		_, err = m.ValidateRefreshToken(pair.AccessToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("同一会话轮换出新的 jti", func(t *testing.T) {
+		next, err := m.GenerateSessionTokenPair(t.Context(), pair.SessionID, "user-1", "u@example.com", "free")
+		require.NoError(t, err)
+		assert.Equal(t, pair.SessionID, next.SessionID)
+		assert.NotEqual(t, pair.RefreshID, next.RefreshID)
+	})
+}
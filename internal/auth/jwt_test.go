@@ -21,7 +21,7 @@ func TestJWTManager_GenerateTokens(t *testing.T) {
 	
 	manager := NewJWTManager(cfg)
 	
-	tokens, err := manager.GenerateTokens("test-user-1", string(domain.RoleUser))
+	tokens, err := manager.GenerateTokens(t.Context(), "test-user-1", string(domain.RoleUser))
 	require.NoError(t, err)
 	
 	assert.NotEmpty(t, tokens.AccessToken)
@@ -41,7 +41,7 @@ func TestJWTManager_ValidateToken(t *testing.T) {
 	manager := NewJWTManager(cfg)
 	
 	// Generate valid token
-	tokens, err := manager.GenerateTokens("test-user-1", string(domain.RoleUser))
+	tokens, err := manager.GenerateTokens(t.Context(), "test-user-1", string(domain.RoleUser))
 	require.NoError(t, err)
 	
 	// Validate token
@@ -78,7 +78,7 @@ func TestJWTManager_ValidateToken_Expired(t *testing.T) {
 	manager := NewJWTManager(cfg)
 	
 	// Generate token
-	tokens, err := manager.GenerateTokens("test-user-1", string(domain.RoleUser))
+	tokens, err := manager.GenerateTokens(t.Context(), "test-user-1", string(domain.RoleUser))
 	require.NoError(t, err)
 	
 	// Wait for expiration
@@ -100,14 +100,14 @@ func TestJWTManager_RefreshToken(t *testing.T) {
 	manager := NewJWTManager(cfg)
 	
 	// Generate initial tokens
-	tokens, err := manager.GenerateTokens("test-user-1", string(domain.RoleUser))
+	tokens, err := manager.GenerateTokens(t.Context(), "test-user-1", string(domain.RoleUser))
 	require.NoError(t, err)
 	
 	// Wait a moment to ensure different timestamps
 	time.Sleep(1 * time.Second)
 	
 	// Refresh tokens
-	newTokens, err := manager.RefreshToken(tokens.RefreshToken)
+	newTokens, err := manager.RefreshToken(t.Context(), tokens.RefreshToken)
 	require.NoError(t, err)
 	
 	assert.NotEmpty(t, newTokens.AccessToken)
@@ -129,7 +129,7 @@ func TestJWTManager_RefreshToken_Invalid(t *testing.T) {
 	manager := NewJWTManager(cfg)
 	
 	// Test invalid refresh token
-	_, err := manager.RefreshToken("invalid-refresh-token")
+	_, err := manager.RefreshToken(t.Context(), "invalid-refresh-token")
 	assert.Error(t, err)
 }
 
@@ -152,7 +152,7 @@ func TestJWTManager_DifferentSecrets(t *testing.T) {
 	manager2 := NewJWTManager(cfg2)
 	
 	// Generate token with manager1
-	tokens, err := manager1.GenerateTokens("test-user-1", string(domain.RoleUser))
+	tokens, err := manager1.GenerateTokens(t.Context(), "test-user-1", string(domain.RoleUser))
 	require.NoError(t, err)
 	
 	// Try to validate with manager2 (different secret)
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 登录防暴力破解策略
+const (
+	LoginDelayAfterFailures = 3                // 账户连续失败达到该次数后，每次尝试先延迟
+	LoginDelay              = 2 * time.Second  // 延迟时长
+	LoginLockAfterFailures  = 5                // 账户连续失败达到该次数后锁定
+	LoginLockDuration       = 15 * time.Minute // 账户锁定时长
+	LoginIPBlockAfter       = 10               // 同一 IP 失败达到该次数后封禁
+	LoginIPBlockDuration    = time.Hour        // IP 封禁时长
+	MaxRecentLoginIPs       = 10               // 每个用户记住的最近登录 IP 数
+)
+
+// EventLoginNewIP 用户从新 IP 登录时推送的 WebSocket 事件
+const EventLoginNewIP = "login_new_ip"
+
+var (
+	// ErrAccountLocked 账户因连续登录失败被锁定
+	ErrAccountLocked = errors.New("account is temporarily locked")
+	// ErrTooManyAttempts 该 IP 登录失败次数过多
+	ErrTooManyAttempts = errors.New("too many login attempts")
+)
+
+// LockedError 账户锁定错误，携带解锁时间（errors.Is 匹配 ErrAccountLocked）
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrAccountLocked, e.Until.Format(time.RFC3339))
+}
+
+func (e *LockedError) Unwrap() error { return ErrAccountLocked }
+
+// LoginCounters 登录失败计数（复用限流计数器，使用独立的 key）
+type LoginCounters interface {
+	IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error)
+	GetRateLimit(ctx context.Context, key string) (int64, error)
+	ResetRateLimit(ctx context.Context, key string) error
+}
+
+// LoginNotifier 向用户推送事件（如 WebSocket Hub）
+type LoginNotifier interface {
+	NotifyUser(userID, event string, data interface{})
+}
+
+// LoginAuditFunc 记录锁定/解锁审计事件；actorID 为空表示系统自动操作
+type LoginAuditFunc func(event, userID, actorID string, until time.Time)
+
+// 审计事件
+const (
+	AuditAccountLocked   = "account_locked"
+	AuditAccountUnlocked = "account_unlocked"
+)
+
+// NewIPEvent 新 IP 登录事件内容
+type NewIPEvent struct {
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// LoginLockStatus 用户登录锁定状态（供管理员排查）
+type LoginLockStatus struct {
+	UserID         string     `json:"userId"`
+	Locked         bool       `json:"locked"`
+	LockedUntil    *time.Time `json:"lockedUntil,omitempty"`
+	FailedAttempts int64      `json:"failedAttempts"`
+}
+
+// LoginGuard 登录失败计数、延迟、账户锁定和 IP 封禁
+type LoginGuard struct {
+	counters LoginCounters
+	notifier LoginNotifier
+	audit    LoginAuditFunc
+	now      func() time.Time
+	sleep    func(time.Duration)
+}
+
+// NewLoginGuard 创建登录保护
+func NewLoginGuard(counters LoginCounters) *LoginGuard {
+	return &LoginGuard{
+		counters: counters,
+		now:      time.Now,
+		sleep:    time.Sleep,
+	}
+}
+
+// SetNotifier 设置新 IP 登录提醒的推送方式
+func (g *LoginGuard) SetNotifier(notifier LoginNotifier) {
+	g.notifier = notifier
+}
+
+// SetAuditFunc 设置锁定/解锁审计回调
+func (g *LoginGuard) SetAuditFunc(fn LoginAuditFunc) {
+	g.audit = fn
+}
+
+func userFailKey(userID string) string { return "login:fail:user:" + userID }
+func ipFailKey(ip string) string       { return "login:fail:ip:" + ip }
+func ipBlockKey(ip string) string      { return "login:block:ip:" + ip }
+
+// ipBlocked IP 是否已被封禁
+func (g *LoginGuard) ipBlocked(ctx context.Context, ip string) bool {
+	if ip == "" {
+		return false
+	}
+	blocked, err := g.counters.GetRateLimit(ctx, ipBlockKey(ip))
+	return err == nil && blocked > 0
+}
+
+// delay 账户已连续失败多次时，在校验密码前等待
+func (g *LoginGuard) delay(ctx context.Context, userID string) {
+	failures, err := g.counters.GetRateLimit(ctx, userFailKey(userID))
+	if err == nil && failures >= LoginDelayAfterFailures {
+		g.sleep(LoginDelay)
+	}
+}
+
+// recordIPFailure 记录 IP 失败次数，达到上限时封禁
+func (g *LoginGuard) recordIPFailure(ctx context.Context, ip string) {
+	if ip == "" {
+		return
+	}
+	failures, err := g.counters.IncrementRateLimit(ctx, ipFailKey(ip), LoginIPBlockDuration)
+	if err == nil && failures >= LoginIPBlockAfter {
+		_, _ = g.counters.IncrementRateLimit(ctx, ipBlockKey(ip), LoginIPBlockDuration)
+		_ = g.counters.ResetRateLimit(ctx, ipFailKey(ip))
+	}
+}
+
+// recordUserFailure 记录账户失败次数，返回是否应锁定
+func (g *LoginGuard) recordUserFailure(ctx context.Context, userID string) bool {
+	failures, err := g.counters.IncrementRateLimit(ctx, userFailKey(userID), LoginLockDuration)
+	return err == nil && failures >= LoginLockAfterFailures
+}
+
+// failures 账户当前连续失败次数
+func (g *LoginGuard) failures(ctx context.Context, userID string) int64 {
+	failures, _ := g.counters.GetRateLimit(ctx, userFailKey(userID))
+	return failures
+}
+
+// reset 清零账户失败次数（登录成功或解锁后）
+func (g *LoginGuard) reset(ctx context.Context, userID string) {
+	_ = g.counters.ResetRateLimit(ctx, userFailKey(userID))
+}
+
+func (g *LoginGuard) auditEvent(event, userID, actorID string, until time.Time) {
+	if g.audit != nil {
+		g.audit(event, userID, actorID, until)
+	}
+}
+
+// rememberIP 记录成功登录的 IP；之前有登录记录且该 IP 首次出现时推送提醒
+// TODO: 通知服务上线后同时发送提醒邮件
+func (g *LoginGuard) rememberIP(userID string, recent []string, ip, userAgent string) ([]string, bool) {
+	if ip == "" {
+		return recent, false
+	}
+	for _, known := range recent {
+		if known == ip {
+			return recent, false
+		}
+	}
+	if len(recent) > 0 && g.notifier != nil {
+		g.notifier.NotifyUser(userID, EventLoginNewIP, NewIPEvent{IP: ip, UserAgent: userAgent, At: g.now()})
+	}
+	updated := append([]string{ip}, recent...)
+	if len(updated) > MaxRecentLoginIPs {
+		updated = updated[:MaxRecentLoginIPs]
+	}
+	return updated, true
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// recordingNotifier 记录推送给用户的事件
+type recordingNotifier struct {
+	events []NewIPEvent
+}
+
+func (n *recordingNotifier) NotifyUser(_ string, event string, data interface{}) {
+	if event == EventLoginNewIP {
+		n.events = append(n.events, data.(NewIPEvent))
+	}
+}
+
+type loginGuardFixture struct {
+	store    *memory.Store
+	service  *Service
+	notifier *recordingNotifier
+	now      time.Time
+	sleeps   []time.Duration
+	audits   []string
+	user     *domain.User
+}
+
+const testPassword = "correct-horse"
+
+func newLoginGuardFixture(t *testing.T) *loginGuardFixture {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	f := &loginGuardFixture{
+		store:    store,
+		service:  NewService(store),
+		notifier: &recordingNotifier{},
+		now:      time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	guard := NewLoginGuard(store)
+	guard.now = func() time.Time { return f.now }
+	guard.sleep = func(d time.Duration) { f.sleeps = append(f.sleeps, d) }
+	guard.SetNotifier(f.notifier)
+	guard.SetAuditFunc(func(event, _, actorID string, _ time.Time) {
+		f.audits = append(f.audits, event+":"+actorID)
+	})
+	f.service.SetLoginGuard(guard)
+
+	user, err := f.service.Register(t.Context(), RegisterInput{Email: "alice@example.com", Password: testPassword, Username: "alice"})
+	require.NoError(t, err)
+	f.user = user
+	return f
+}
+
+func (f *loginGuardFixture) login(password, ip string) error {
+	_, err := f.service.Login(context.Background(), LoginInput{Identifier: "alice@example.com", Password: password, IP: ip})
+	return err
+}
+
+func TestLoginGuard(t *testing.T) {
+	t.Run("连续失败三次后每次尝试延迟", func(t *testing.T) {
+		f := newLoginGuardFixture(t)
+		for i := 0; i < LoginDelayAfterFailures; i++ {
+			assert.ErrorIs(t, f.login("wrong", "10.0.0.1"), ErrInvalidCredentials)
+		}
+		assert.Empty(t, f.sleeps, "前三次不延迟")
+
+		assert.ErrorIs(t, f.login("wrong", "10.0.0.1"), ErrInvalidCredentials)
+		assert.Equal(t, []time.Duration{LoginDelay}, f.sleeps)
+	})
+
+	t.Run("第五次失败锁定账户，到期后自动解锁", func(t *testing.T) {
+		f := newLoginGuardFixture(t)
+		for i := 0; i < LoginLockAfterFailures-1; i++ {
+			require.ErrorIs(t, f.login("wrong", "10.0.0.1"), ErrInvalidCredentials)
+		}
+
+		err := f.login("wrong", "10.0.0.1")
+		var locked *LockedError
+		require.True(t, errors.As(err, &locked))
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		assert.Equal(t, f.now.Add(LoginLockDuration), locked.Until)
+
+		f.now = f.now.Add(LoginLockDuration - time.Second)
+		assert.ErrorIs(t, f.login(testPassword, "10.0.0.1"), ErrAccountLocked, "锁定期间正确密码也被拒绝")
+
+		f.now = f.now.Add(2 * time.Second)
+		require.NoError(t, f.login(testPassword, "10.0.0.1"))
+		stored, err := f.store.GetUserByID(t.Context(), f.user.ID)
+		require.NoError(t, err)
+		assert.Nil(t, stored.LockedUntil)
+		assert.Equal(t, []string{AuditAccountLocked + ":", AuditAccountUnlocked + ":"}, f.audits)
+	})
+
+	t.Run("登录成功清零账户失败次数", func(t *testing.T) {
+		f := newLoginGuardFixture(t)
+		for i := 0; i < LoginLockAfterFailures-1; i++ {
+			require.ErrorIs(t, f.login("wrong", "10.0.0.1"), ErrInvalidCredentials)
+		}
+		require.NoError(t, f.login(testPassword, "10.0.0.1"))
+
+		status, err := f.service.LoginLockStatus(t.Context(), f.user.ID)
+		require.NoError(t, err)
+		assert.Zero(t, status.FailedAttempts)
+		assert.ErrorIs(t, f.login("wrong", "10.0.0.1"), ErrInvalidCredentials, "重新计数，不会立即锁定")
+	})
+
+	t.Run("同一 IP 失败十次后封禁", func(t *testing.T) {
+		f := newLoginGuardFixture(t)
+		for i := 0; i < LoginIPBlockAfter; i++ {
+			_, err := f.service.Login(t.Context(), LoginInput{Identifier: "nobody@example.com", Password: "wrong", IP: "10.0.0.9"})
+			require.ErrorIs(t, err, ErrInvalidCredentials)
+		}
+
+		assert.ErrorIs(t, f.login(testPassword, "10.0.0.9"), ErrTooManyAttempts)
+		assert.NoError(t, f.login(testPassword, "10.0.0.10"), "其他 IP 不受影响")
+	})
+
+	t.Run("新 IP 登录每个 IP 只提醒一次", func(t *testing.T) {
+		f := newLoginGuardFixture(t)
+		require.NoError(t, f.login(testPassword, "10.0.0.1"))
+		assert.Empty(t, f.notifier.events, "首次登录不提醒")
+
+		require.NoError(t, f.login(testPassword, "10.0.0.2"))
+		require.NoError(t, f.login(testPassword, "10.0.0.2"))
+		require.NoError(t, f.login(testPassword, "10.0.0.1"))
+		require.Len(t, f.notifier.events, 1)
+		assert.Equal(t, "10.0.0.2", f.notifier.events[0].IP)
+	})
+
+	t.Run("管理员解除锁定", func(t *testing.T) {
+		f := newLoginGuardFixture(t)
+		for i := 0; i < LoginLockAfterFailures; i++ {
+			_ = f.login("wrong", "10.0.0.1")
+		}
+		status, err := f.service.LoginLockStatus(t.Context(), f.user.ID)
+		require.NoError(t, err)
+		assert.True(t, status.Locked)
+
+		require.NoError(t, f.service.ClearLoginLock(t.Context(), f.user.ID, "admin-1"))
+		status, err = f.service.LoginLockStatus(t.Context(), f.user.ID)
+		require.NoError(t, err)
+		assert.False(t, status.Locked)
+		assert.Equal(t, AuditAccountUnlocked+":admin-1", f.audits[len(f.audits)-1])
+		assert.NoError(t, f.login(testPassword, "10.0.0.1"))
+	})
+}
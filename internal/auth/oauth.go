@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+var (
+	// ErrOAuthProviderNotFound 未配置该第三方登录提供方
+	ErrOAuthProviderNotFound = errors.New("oauth provider not found")
+	// ErrOAuthEmailUnverified 提供方未返回已验证的邮箱，无法关联或创建账户
+	ErrOAuthEmailUnverified = errors.New("oauth email not verified")
+	// ErrOAuthExchange 授权码换取令牌或校验身份失败
+	ErrOAuthExchange = errors.New("oauth exchange failed")
+)
+
+// ExternalIdentity 第三方提供方返回的用户身份
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string // 提供方的用户唯一标识
+	Email         string
+	EmailVerified bool
+	Name          string // 显示名或登录名，用于生成新用户的用户名
+}
+
+// OAuthProvider 第三方登录提供方（授权码流程，state/nonce/PKCE 由调用方生成并在回调时回传）
+type OAuthProvider interface {
+	// Name 提供方名称，用于路由（/v1/auth/oauth/:provider）和身份关联
+	Name() string
+	// AuthCodeURL 返回跳转到提供方的授权地址
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	// Exchange 用授权码换取令牌并返回用户身份，失败时返回包装 ErrOAuthExchange 的错误
+	Exchange(ctx context.Context, code, nonce, verifier string) (*ExternalIdentity, error)
+}
+
+// OAuthIdentityRepository 第三方登录身份存储接口
+type OAuthIdentityRepository interface {
+	CreateOAuthIdentity(ctx context.Context, identity *domain.OAuthIdentity) error
+	GetOAuthIdentity(ctx context.Context, provider, subject string) (*domain.OAuthIdentity, error)
+	TouchOAuthIdentity(ctx context.Context, id string, at time.Time) error
+}
+
+// OAuthService 第三方登录服务
+//
+// 已关联的身份直接登录；首次登录时按提供方确认过的邮箱关联到已有账户，没有对应账户时自动创建。
+type OAuthService struct {
+	userRepo   UserRepository
+	identities OAuthIdentityRepository
+	providers  map[string]OAuthProvider
+	now        func() time.Time
+}
+
+// NewOAuthService 创建第三方登录服务
+func NewOAuthService(userRepo UserRepository, identities OAuthIdentityRepository) *OAuthService {
+	return &OAuthService{
+		userRepo:   userRepo,
+		identities: identities,
+		providers:  make(map[string]OAuthProvider),
+		now:        time.Now,
+	}
+}
+
+// Register 注册提供方（同名覆盖）
+func (s *OAuthService) Register(provider OAuthProvider) {
+	s.providers[provider.Name()] = provider
+}
+
+// Provider 按名称获取提供方，未配置时返回 ErrOAuthProviderNotFound
+func (s *OAuthService) Provider(name string) (OAuthProvider, error) {
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, ErrOAuthProviderNotFound
+	}
+	return provider, nil
+}
+
+// Providers 已配置的提供方名称（按名称排序）
+func (s *OAuthService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Login 用第三方身份登录，返回对应的用户（必要时关联或创建）
+func (s *OAuthService) Login(ctx context.Context, ext *ExternalIdentity) (*domain.User, error) {
+	identity, err := s.identities.GetOAuthIdentity(ctx, ext.Provider, ext.Subject)
+	switch {
+	case err == nil:
+		return s.loginLinked(ctx, identity)
+	case !errors.Is(err, storage.ErrOAuthIdentityNotFound):
+		return nil, fmt.Errorf("failed to get oauth identity: %w", err)
+	}
+
+	// 未关联的身份只接受提供方确认过的邮箱，否则任何人都能用伪造的邮箱接管同名账户
+	email := strings.ToLower(strings.TrimSpace(ext.Email))
+	if !ext.EmailVerified || !ValidateEmail(email) {
+		return nil, ErrOAuthEmailUnverified
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err == nil {
+		if !user.IsActive {
+			return nil, ErrUserInactive
+		}
+		if err := s.claimUnverified(ctx, user); err != nil {
+			return nil, err
+		}
+	} else if user, err = s.createUser(ctx, email, ext.Name); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	identity = &domain.OAuthIdentity{
+		ID:          uuid.New().String(),
+		UserID:      user.ID,
+		Provider:    ext.Provider,
+		Subject:     ext.Subject,
+		Email:       email,
+		CreatedAt:   now,
+		LastLoginAt: &now,
+	}
+	if err := s.identities.CreateOAuthIdentity(ctx, identity); err != nil {
+		if !errors.Is(err, storage.ErrOAuthIdentityExists) {
+			return nil, fmt.Errorf("failed to link oauth identity: %w", err)
+		}
+		// 并发回调已经完成关联，按已关联身份登录
+		if identity, err = s.identities.GetOAuthIdentity(ctx, ext.Provider, ext.Subject); err != nil {
+			return nil, fmt.Errorf("failed to get oauth identity: %w", err)
+		}
+		return s.loginLinked(ctx, identity)
+	}
+
+	_ = s.userRepo.UpdateLastLogin(ctx, user.ID)
+	return user, nil
+}
+
+// loginLinked 已关联身份登录
+func (s *OAuthService) loginLinked(ctx context.Context, identity *domain.OAuthIdentity) (*domain.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, identity.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+	_ = s.identities.TouchOAuthIdentity(ctx, identity.ID, s.now())
+	_ = s.userRepo.UpdateLastLogin(ctx, user.ID)
+	return user, nil
+}
+
+// claimUnverified 关联到邮箱未验证的账户时，提供方已证明邮箱归属：标记为已验证并清空密码，
+// 防止他人抢先用该邮箱注册后保留密码登录（账户预占）
+func (s *OAuthService) claimUnverified(ctx context.Context, user *domain.User) error {
+	if user.IsEmailVerified {
+		return nil
+	}
+	user.IsEmailVerified = true
+	user.PasswordHash = ""
+	user.UpdatedAt = s.now()
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// createUser 自动创建第三方登录的用户（没有密码，只能通过第三方登录）
+func (s *OAuthService) createUser(ctx context.Context, email, name string) (*domain.User, error) {
+	now := s.now()
+	user := &domain.User{
+		ID:              uuid.New().String(),
+		Email:           email,
+		Username:        s.uniqueUsername(ctx, email, name),
+		Role:            domain.RoleUser,
+		Tier:            domain.TierFree,
+		IsActive:        true,
+		IsEmailVerified: true,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9._\-]+`)
+
+// uniqueUsername 由显示名（为空时用邮箱前缀）生成未被占用的用户名，重名时追加随机后缀
+func (s *OAuthService) uniqueUsername(ctx context.Context, email, name string) string {
+	base := usernameInvalidChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "")
+	if base == "" {
+		base = usernameInvalidChars.ReplaceAllString(strings.SplitN(email, "@", 2)[0], "")
+	}
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > 32 {
+		base = base[:32]
+	}
+
+	candidate := base
+	for range 5 {
+		if user, err := s.userRepo.GetUserByUsername(ctx, candidate); err != nil || user == nil {
+			return candidate
+		}
+		candidate = base + "-" + uuid.New().String()[:6]
+	}
+	return base + "-" + uuid.New().String()[:8]
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// GoogleIssuer Google 的 OIDC 签发者
+const GoogleIssuer = "https://accounts.google.com"
+
+// OIDCConfig OpenID Connect 提供方配置（Google 或自定义 IdP）
+type OIDCConfig struct {
+	Name         string // 提供方名称，如 google、okta
+	IssuerURL    string // 签发者地址，启动后首次使用时通过 /.well-known/openid-configuration 发现端点
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string   // 回调地址（/v1/auth/oauth/:provider/callback 的完整 URL）
+	Scopes       []string // 为空时使用 openid email profile
+}
+
+// OIDCProvider OpenID Connect 提供方：校验 ID Token 的签名、受众和 nonce，从声明中读取邮箱
+type OIDCProvider struct {
+	cfg OIDCConfig
+
+	mu       sync.Mutex
+	provider *oidc.Provider // 发现成功后缓存；失败时下次请求重试，IdP 暂时不可用不影响启动
+}
+
+// NewOIDCProvider 创建 OpenID Connect 提供方
+func NewOIDCProvider(cfg OIDCConfig) *OIDCProvider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"email", "profile"}
+	}
+	if !slices.Contains(cfg.Scopes, oidc.ScopeOpenID) {
+		cfg.Scopes = append([]string{oidc.ScopeOpenID}, cfg.Scopes...)
+	}
+	return &OIDCProvider{cfg: cfg}
+}
+
+// Name 提供方名称
+func (p *OIDCProvider) Name() string { return p.cfg.Name }
+
+// discover 获取（必要时发现）提供方元数据
+func (p *OIDCProvider) discover(ctx context.Context) (*oidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.provider != nil {
+		return p.provider, nil
+	}
+	// 发现结果中的 JWKS 地址会在之后的请求中使用，不能绑定到当前请求的上下文
+	provider, err := oidc.NewProvider(context.WithoutCancel(ctx), p.cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s: %w", p.cfg.Name, err)
+	}
+	p.provider = provider
+	return provider, nil
+}
+
+func (p *OIDCProvider) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       p.cfg.Scopes,
+	}
+}
+
+// AuthCodeURL 返回授权地址（带 nonce 和 PKCE S256 质询）
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauth2Config(provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange 换取令牌并校验 ID Token
+func (p *OIDCProvider) Exchange(ctx context.Context, code, nonce, verifier string) (*ExternalIdentity, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := p.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOAuthExchange, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("%w: missing id_token", ErrOAuthExchange)
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOAuthExchange, err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrOAuthExchange)
+	}
+
+	var claims struct {
+		Email         string          `json:"email"`
+		EmailVerified json.RawMessage `json:"email_verified"` // 部分 IdP 返回字符串 "true"
+		Name          string          `json:"name"`
+		Username      string          `json:"preferred_username"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOAuthExchange, err)
+	}
+	name := claims.Username
+	if name == "" {
+		name = claims.Name
+	}
+	verified := strings.Trim(string(claims.EmailVerified), `"`)
+	return &ExternalIdentity{
+		Provider:      p.cfg.Name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: verified == "true",
+		Name:          name,
+	}, nil
+}
+
+// GitHubConfig GitHub OAuth App 配置
+type GitHubConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// 以下为空时使用 github.com 的地址（GitHub Enterprise 或测试时覆盖）
+	AuthURL  string
+	TokenURL string
+	APIURL   string
+}
+
+// GitHubProvider GitHub 登录（GitHub 不支持 OIDC，身份取自 /user 和 /user/emails 接口）
+type GitHubProvider struct {
+	oauth  *oauth2.Config
+	apiURL string
+}
+
+// NewGitHubProvider 创建 GitHub 提供方
+func NewGitHubProvider(cfg GitHubConfig) *GitHubProvider {
+	authURL, tokenURL, apiURL := cfg.AuthURL, cfg.TokenURL, cfg.APIURL
+	if authURL == "" {
+		authURL = "https://github.com/login/oauth/authorize"
+	}
+	if tokenURL == "" {
+		tokenURL = "https://github.com/login/oauth/access_token"
+	}
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	return &GitHubProvider{
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     oauth2.Endpoint{AuthURL: authURL, TokenURL: tokenURL},
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       []string{"read:user", "user:email"},
+		},
+		apiURL: strings.TrimRight(apiURL, "/"),
+	}
+}
+
+// Name 提供方名称
+func (p *GitHubProvider) Name() string { return "github" }
+
+// AuthCodeURL 返回授权地址（GitHub 不使用 nonce，只带 PKCE 质询）
+func (p *GitHubProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	return p.oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange 换取令牌并读取用户和主邮箱
+func (p *GitHubProvider) Exchange(ctx context.Context, code, nonce, verifier string) (*ExternalIdentity, error) {
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOAuthExchange, err)
+	}
+	client := p.oauth.Client(ctx, token)
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	}
+	if err := p.get(ctx, client, "/user", &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, fmt.Errorf("%w: missing user id", ErrOAuthExchange)
+	}
+
+	// /user 中的 email 是公开邮箱，不代表已验证，以 /user/emails 中已验证的主邮箱为准
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, client, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+	identity := &ExternalIdentity{Provider: p.Name(), Subject: strconv.FormatInt(user.ID, 10), Name: user.Login}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+			break
+		}
+	}
+	return identity, nil
+}
+
+// get 请求 GitHub API 并解码 JSON 响应
+func (p *GitHubProvider) get(ctx context.Context, client *http.Client, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOAuthExchange, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: GET %s returned %d", ErrOAuthExchange, path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrOAuthExchange, err)
+	}
+	return nil
+}
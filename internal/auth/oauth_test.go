@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestOAuthService_Login(t *testing.T) {
+	store := memory.NewStore(time.Hour)
+	svc := NewOAuthService(store, store)
+
+	t.Run("首次登录自动创建用户", func(t *testing.T) {
+		user, err := svc.Login(t.Context(), &ExternalIdentity{
+			Provider: "google", Subject: "g-1", Email: "New.User@Example.com", EmailVerified: true, Name: "New User",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "new.user@example.com", user.Email)
+		assert.Equal(t, "newuser", user.Username)
+		assert.True(t, user.IsEmailVerified)
+		assert.Empty(t, user.PasswordHash, "第三方登录创建的用户没有密码")
+		assert.Equal(t, domain.TierFree, user.Tier)
+
+		identities, err := store.ListOAuthIdentities(t.Context(), user.ID)
+		require.NoError(t, err)
+		require.Len(t, identities, 1)
+		assert.Equal(t, "google", identities[0].Provider)
+		assert.Equal(t, "g-1", identities[0].Subject)
+	})
+
+	t.Run("已关联的身份直接登录", func(t *testing.T) {
+		first, err := store.GetUserByEmail(t.Context(), "new.user@example.com")
+		require.NoError(t, err)
+
+		// 提供方的邮箱变更或未验证不影响已关联身份
+		user, err := svc.Login(t.Context(), &ExternalIdentity{Provider: "google", Subject: "g-1", Email: "other@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, user.ID)
+	})
+
+	t.Run("按已验证邮箱关联已有账户", func(t *testing.T) {
+		hash, err := HashPassword("password123")
+		require.NoError(t, err)
+		require.NoError(t, store.CreateUser(t.Context(), &domain.User{
+			ID: "user-verified", Email: "verified@example.com", Username: "verified", PasswordHash: hash,
+			Tier: domain.TierPro, IsActive: true, IsEmailVerified: true,
+		}))
+
+		user, err := svc.Login(t.Context(), &ExternalIdentity{
+			Provider: "github", Subject: "42", Email: "verified@example.com", EmailVerified: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "user-verified", user.ID)
+		assert.Equal(t, domain.TierPro, user.Tier)
+		assert.True(t, CheckPassword("password123", user.PasswordHash), "已验证邮箱的账户保留密码")
+	})
+
+	t.Run("关联未验证邮箱的账户时清空密码", func(t *testing.T) {
+		hash, err := HashPassword("password123")
+		require.NoError(t, err)
+		require.NoError(t, store.CreateUser(t.Context(), &domain.User{
+			ID: "user-unverified", Email: "victim@example.com", Username: "victim", PasswordHash: hash, IsActive: true,
+		}))
+
+		user, err := svc.Login(t.Context(), &ExternalIdentity{
+			Provider: "google", Subject: "g-victim", Email: "victim@example.com", EmailVerified: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "user-unverified", user.ID)
+
+		stored, err := store.GetUserByID(t.Context(), "user-unverified")
+		require.NoError(t, err)
+		assert.True(t, stored.IsEmailVerified)
+		assert.False(t, CheckPassword("password123", stored.PasswordHash), "抢先注册者设置的密码失效")
+	})
+
+	t.Run("邮箱未验证时拒绝", func(t *testing.T) {
+		_, err := svc.Login(t.Context(), &ExternalIdentity{
+			Provider: "github", Subject: "43", Email: "verified@example.com", EmailVerified: false,
+		})
+		assert.ErrorIs(t, err, ErrOAuthEmailUnverified)
+
+		_, err = svc.Login(t.Context(), &ExternalIdentity{Provider: "github", Subject: "44", EmailVerified: true})
+		assert.ErrorIs(t, err, ErrOAuthEmailUnverified)
+	})
+
+	t.Run("禁用的用户不能登录", func(t *testing.T) {
+		require.NoError(t, store.CreateUser(t.Context(), &domain.User{
+			ID: "user-inactive", Email: "inactive@example.com", Username: "inactive", IsEmailVerified: true,
+		}))
+		_, err := svc.Login(t.Context(), &ExternalIdentity{
+			Provider: "google", Subject: "g-inactive", Email: "inactive@example.com", EmailVerified: true,
+		})
+		assert.ErrorIs(t, err, ErrUserInactive)
+	})
+
+	t.Run("用户名已被占用时追加后缀", func(t *testing.T) {
+		user, err := svc.Login(t.Context(), &ExternalIdentity{
+			Provider: "google", Subject: "g-2", Email: "someone@example.com", EmailVerified: true, Name: "verified",
+		})
+		require.NoError(t, err)
+		assert.Regexp(t, `^verified-[0-9a-f]{6}$`, user.Username)
+	})
+}
+
+// fakeIdP 测试用 OIDC 提供方：发现文档、JWKS 和令牌端点
+type fakeIdP struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	claims   jwt.MapClaims // 令牌端点签发的 ID Token 附加声明
+	lastForm url.Values    // 最近一次令牌请求
+}
+
+func newFakeIdP(t *testing.T, clientID string) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &fakeIdP{key: key, clientID: clientID}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "test-key", "alg": "RS256", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		idp.lastForm = r.PostForm
+		if r.PostForm.Get("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss": idp.server.URL,
+			"aud": idp.clientID,
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"iat": time.Now().Unix(),
+		}
+		for k, v := range idp.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test-key"
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "at", "token_type": "Bearer", "expires_in": 3600, "id_token": signed,
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func TestOIDCProvider(t *testing.T) {
+	idp := newFakeIdP(t, "tempmail")
+	provider := NewOIDCProvider(OIDCConfig{
+		Name: "corp", IssuerURL: idp.server.URL, ClientID: "tempmail", ClientSecret: "secret",
+		RedirectURL: "https://api.example.com/v1/auth/oauth/corp/callback",
+	})
+
+	t.Run("授权地址带nonce和PKCE质询", func(t *testing.T) {
+		authURL, err := provider.AuthCodeURL(t.Context(), "state-1", "nonce-1", "verifier-1")
+		require.NoError(t, err)
+		u, err := url.Parse(authURL)
+		require.NoError(t, err)
+		assert.Equal(t, idp.server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+		q := u.Query()
+		assert.Equal(t, "state-1", q.Get("state"))
+		assert.Equal(t, "nonce-1", q.Get("nonce"))
+		assert.Equal(t, "S256", q.Get("code_challenge_method"))
+		assert.NotEmpty(t, q.Get("code_challenge"))
+		assert.Equal(t, "openid email profile", q.Get("scope"))
+	})
+
+	t.Run("换取令牌并校验ID Token", func(t *testing.T) {
+		idp.claims = jwt.MapClaims{
+			"sub": "corp-7", "nonce": "nonce-1", "email": "dev@corp.example", "email_verified": "true", "preferred_username": "dev",
+		}
+		identity, err := provider.Exchange(t.Context(), "good-code", "nonce-1", "verifier-1")
+		require.NoError(t, err)
+		assert.Equal(t, &ExternalIdentity{
+			Provider: "corp", Subject: "corp-7", Email: "dev@corp.example", EmailVerified: true, Name: "dev",
+		}, identity)
+		assert.Equal(t, "verifier-1", idp.lastForm.Get("code_verifier"))
+	})
+
+	t.Run("nonce不一致时拒绝", func(t *testing.T) {
+		idp.claims = jwt.MapClaims{"sub": "corp-7", "nonce": "other"}
+		_, err := provider.Exchange(t.Context(), "good-code", "nonce-1", "verifier-1")
+		assert.ErrorIs(t, err, ErrOAuthExchange)
+	})
+
+	t.Run("受众不是本客户端时拒绝", func(t *testing.T) {
+		other := NewOIDCProvider(OIDCConfig{Name: "corp", IssuerURL: idp.server.URL, ClientID: "another-client"})
+		idp.claims = jwt.MapClaims{"sub": "corp-7", "nonce": "nonce-1"}
+		_, err := other.Exchange(t.Context(), "good-code", "nonce-1", "verifier-1")
+		assert.ErrorIs(t, err, ErrOAuthExchange)
+	})
+
+	t.Run("授权码无效", func(t *testing.T) {
+		_, err := provider.Exchange(t.Context(), "bad-code", "nonce-1", "verifier-1")
+		assert.ErrorIs(t, err, ErrOAuthExchange)
+	})
+}
+
+func TestGitHubProvider(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"gh-token","token_type":"bearer"}`))
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer gh-token", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"id":12345,"login":"octocat","email":"public@example.com"}`))
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"email":"old@example.com","primary":false,"verified":true},{"email":"octo@example.com","primary":true,"verified":true}]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	provider := NewGitHubProvider(GitHubConfig{
+		ClientID: "gh-client", ClientSecret: "secret", RedirectURL: "https://api.example.com/v1/auth/oauth/github/callback",
+		AuthURL: server.URL + "/login/oauth/authorize", TokenURL: server.URL + "/login/oauth/access_token", APIURL: server.URL,
+	})
+
+	authURL, err := provider.AuthCodeURL(t.Context(), "state-1", "nonce-1", "verifier-1")
+	require.NoError(t, err)
+	assert.Contains(t, authURL, "code_challenge_method=S256")
+	assert.NotContains(t, authURL, "nonce=")
+
+	identity, err := provider.Exchange(t.Context(), "code", "", "verifier-1")
+	require.NoError(t, err)
+	assert.Equal(t, &ExternalIdentity{
+		Provider: "github", Subject: "12345", Email: "octo@example.com", EmailVerified: true, Name: "octocat",
+	}, identity, "以已验证的主邮箱为准，而不是公开邮箱")
+}
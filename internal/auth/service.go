@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"regexp"
@@ -35,26 +36,37 @@ var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-
 // Service 认证服务
 type Service struct {
 	userRepo UserRepository
+	guard    *LoginGuard // 登录防暴力破解（可选）
+
+	totpCipher *SecretCipher // 两步验证密钥加密器（未设置时不能启用两步验证）
+	totpIssuer string        // 认证器应用中显示的签发方
+	now        func() time.Time
 }
 
 // UserRepository 用户存储接口
 type UserRepository interface {
-	CreateUser(user *domain.User) error
-	GetUserByID(id string) (*domain.User, error)
-	GetUserByEmail(email string) (*domain.User, error)
-	GetUserByUsername(username string) (*domain.User, error)
-	UpdateUser(user *domain.User) error
-	UpdateLastLogin(userID string) error
-	GetUserByAPIKey(apiKey string) (*domain.User, error)
+	CreateUser(ctx context.Context, user *domain.User) error
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*domain.User, error)
+	UpdateUser(ctx context.Context, user *domain.User) error
+	UpdateLastLogin(ctx context.Context, userID string) error
+	GetUserByAPIKey(ctx context.Context, apiKey string) (*domain.User, error)
 }
 
 // NewService 创建认证服务
 func NewService(userRepo UserRepository) *Service {
 	return &Service{
 		userRepo: userRepo,
+		now:      time.Now,
 	}
 }
 
+// SetLoginGuard 设置登录防暴力破解（不设置时不限制失败次数）
+func (s *Service) SetLoginGuard(guard *LoginGuard) {
+	s.guard = guard
+}
+
 // RegisterInput 注册输入
 type RegisterInput struct {
 	Email    string
@@ -66,10 +78,12 @@ type RegisterInput struct {
 type LoginInput struct {
 	Identifier string
 	Password   string
+	IP         string // 客户端 IP（用于失败计数和新 IP 提醒）
+	UserAgent  string
 }
 
 // Register 用户注册
-func (s *Service) Register(input RegisterInput) (*domain.User, error) {
+func (s *Service) Register(ctx context.Context, input RegisterInput) (*domain.User, error) {
 	// 验证邮箱格式
 	if !ValidateEmail(input.Email) {
 		return nil, ErrInvalidEmail
@@ -81,12 +95,12 @@ func (s *Service) Register(input RegisterInput) (*domain.User, error) {
 	}
 
 	// 检查邮箱是否已存在
-	if user, err := s.userRepo.GetUserByEmail(strings.ToLower(input.Email)); err == nil && user != nil {
+	if user, err := s.userRepo.GetUserByEmail(ctx, strings.ToLower(input.Email)); err == nil && user != nil {
 		return nil, ErrEmailExists
 	}
 
 	// 检查用户名是否已存在
-	if user, err := s.userRepo.GetUserByUsername(strings.ToLower(input.Username)); err == nil && user != nil {
+	if user, err := s.userRepo.GetUserByUsername(ctx, strings.ToLower(input.Username)); err == nil && user != nil {
 		return nil, ErrUsernameExists
 	}
 
@@ -111,7 +125,7 @@ func (s *Service) Register(input RegisterInput) (*domain.User, error) {
 		UpdatedAt:       now,
 	}
 
-	if err := s.userRepo.CreateUser(user); err != nil {
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -119,15 +133,25 @@ func (s *Service) Register(input RegisterInput) (*domain.User, error) {
 }
 
 // Login 用户登录
-func (s *Service) Login(input LoginInput) (*domain.User, error) {
+//
+// 返回用户的 TOTPEnabled 为 true 时登录尚未完成，调用方不能签发令牌，应要求输入两步验证码。
+func (s *Service) Login(ctx context.Context, input LoginInput) (*domain.User, error) {
 	identifier := strings.ToLower(input.Identifier)
 
+	// 失败次数过多的 IP 直接拒绝
+	if s.guard != nil && s.guard.ipBlocked(ctx, input.IP) {
+		return nil, ErrTooManyAttempts
+	}
+
 	// 优先按邮箱查找
-	user, err := s.userRepo.GetUserByEmail(identifier)
+	user, err := s.userRepo.GetUserByEmail(ctx, identifier)
 	if err != nil {
 		// 如果按邮箱查找失败，尝试按用户名查找
-		user, err = s.userRepo.GetUserByUsername(identifier)
+		user, err = s.userRepo.GetUserByUsername(ctx, identifier)
 		if err != nil {
+			if s.guard != nil {
+				s.guard.recordIPFailure(ctx, input.IP)
+			}
 			return nil, ErrInvalidCredentials
 		}
 	}
@@ -137,36 +161,140 @@ func (s *Service) Login(input LoginInput) (*domain.User, error) {
 		return nil, ErrUserInactive
 	}
 
+	if s.guard != nil {
+		if err := s.checkLock(ctx, user); err != nil {
+			return nil, err
+		}
+		s.guard.delay(ctx, user.ID)
+	}
+
 	// 验证密码
 	if !CheckPassword(input.Password, user.PasswordHash) {
-		return nil, ErrInvalidCredentials
+		return nil, s.loginFailed(ctx, user, input.IP)
+	}
+
+	// 启用了两步验证时密码只是第一步：不清零失败次数，由调用方签发质询后 VerifyTwoFactorLogin 完成登录
+	if user.TOTPEnabled {
+		return user, nil
+	}
+
+	if s.guard != nil {
+		s.loginSucceeded(ctx, user, input)
 	}
 
 	// 更新最后登录时间
-	_ = s.userRepo.UpdateLastLogin(user.ID)
+	_ = s.userRepo.UpdateLastLogin(ctx, user.ID)
 
 	return user, nil
 }
 
+// checkLock 账户锁定中返回 LockedError；锁定已过期时解除
+func (s *Service) checkLock(ctx context.Context, user *domain.User) error {
+	if user.LockedUntil == nil {
+		return nil
+	}
+	until := *user.LockedUntil
+	if s.guard.now().Before(until) {
+		return &LockedError{Until: until}
+	}
+	user.LockedUntil = nil
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("failed to unlock user: %w", err)
+	}
+	s.guard.auditEvent(AuditAccountUnlocked, user.ID, "", until)
+	return nil
+}
+
+// loginFailed 记录密码错误，账户失败次数达到上限时锁定
+func (s *Service) loginFailed(ctx context.Context, user *domain.User, ip string) error {
+	if s.guard == nil {
+		return ErrInvalidCredentials
+	}
+	s.guard.recordIPFailure(ctx, ip)
+	if !s.guard.recordUserFailure(ctx, user.ID) {
+		return ErrInvalidCredentials
+	}
+
+	until := s.guard.now().Add(LoginLockDuration)
+	user.LockedUntil = &until
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	s.guard.reset(ctx, user.ID)
+	s.guard.auditEvent(AuditAccountLocked, user.ID, "", until)
+	return &LockedError{Until: until}
+}
+
+// loginSucceeded 清零失败次数，记录登录 IP（IP 失败计数不清零，避免用一个账户为其他账户的爆破解封）
+func (s *Service) loginSucceeded(ctx context.Context, user *domain.User, input LoginInput) {
+	s.guard.reset(ctx, user.ID)
+	if recent, changed := s.guard.rememberIP(user.ID, user.RecentLoginIPs, input.IP, input.UserAgent); changed {
+		user.RecentLoginIPs = recent
+		_ = s.userRepo.UpdateUser(ctx, user)
+	}
+}
+
+// LoginLockStatus 查询用户的登录锁定状态
+func (s *Service) LoginLockStatus(ctx context.Context, userID string) (*LoginLockStatus, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	status := &LoginLockStatus{UserID: user.ID}
+	if s.guard != nil {
+		status.FailedAttempts = s.guard.failures(ctx, user.ID)
+		if user.LockedUntil != nil && s.guard.now().Before(*user.LockedUntil) {
+			status.Locked = true
+			status.LockedUntil = user.LockedUntil
+		}
+	}
+	return status, nil
+}
+
+// ClearLoginLock 管理员解除用户锁定并清零失败次数
+func (s *Service) ClearLoginLock(ctx context.Context, userID, actorID string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if s.guard == nil {
+		return nil
+	}
+	s.guard.reset(ctx, user.ID)
+	if user.LockedUntil == nil {
+		return nil
+	}
+	until := *user.LockedUntil
+	user.LockedUntil = nil
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("failed to unlock user: %w", err)
+	}
+	s.guard.auditEvent(AuditAccountUnlocked, user.ID, actorID, until)
+	return nil
+}
+
 // GetUserByID 根据 ID 获取用户
-func (s *Service) GetUserByID(userID string) (*domain.User, error) {
-	user, err := s.userRepo.GetUserByID(userID)
+func (s *Service) GetUserByID(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
 	return user, nil
 }
 
-// ChangePassword 修改密码
-func (s *Service) ChangePassword(userID, oldPassword, newPassword string) error {
-	user, err := s.userRepo.GetUserByID(userID)
+// ChangePassword 修改密码（需要当前密码，错误计入登录失败次数）
+func (s *Service) ChangePassword(ctx context.Context, userID, oldPassword, newPassword, ip string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return ErrUserNotFound
 	}
 
 	// 验证旧密码
-	if !CheckPassword(oldPassword, user.PasswordHash) {
-		return errors.New("invalid old password")
+	if err := s.verifyPassword(ctx, user, oldPassword, ip); err != nil {
+		if errors.Is(err, ErrIncorrectPassword) {
+			return fmt.Errorf("invalid old password: %w", err)
+		}
+		return err
 	}
 
 	// 验证新密码强度
@@ -181,7 +309,8 @@ func (s *Service) ChangePassword(userID, oldPassword, newPassword string) error
 	}
 
 	user.PasswordHash = newHash
-	return s.userRepo.UpdateUser(user)
+	user.AppPasswordGeneration++ // 旧应用专用密码随旧密码一起失效
+	return s.userRepo.UpdateUser(ctx, user)
 }
 
 // ValidateEmail 验证邮箱格式
@@ -216,13 +345,13 @@ func CheckPassword(password, hash string) bool {
 }
 
 // ValidateAPIKey 验证API Key并返回用户
-func (s *Service) ValidateAPIKey(apiKey string) (*domain.User, error) {
+func (s *Service) ValidateAPIKey(ctx context.Context, apiKey string) (*domain.User, error) {
 	if apiKey == "" {
 		return nil, errors.New("API key is required")
 	}
 
 	// 从存储中获取API Key对应的用户
-	user, err := s.userRepo.GetUserByAPIKey(apiKey)
+	user, err := s.userRepo.GetUserByAPIKey(ctx, apiKey)
 	if err != nil {
 		return nil, ErrInvalidCredentials
 	}
@@ -31,7 +31,7 @@ func TestAuthService_Register(t *testing.T) {
 		Password: "Password123!",
 	}
 
-	response, err := service.Register(req)
+	response, err := service.Register(t.Context(), req)
 	require.NoError(t, err)
 	assert.NotEmpty(t, response.User.ID)
 	assert.Equal(t, "testuser", response.User.Username)
@@ -58,7 +58,7 @@ func TestAuthService_Register_DuplicateUsername(t *testing.T) {
 		Email:    "test1@example.com",
 		Password: "Password123!",
 	}
-	_, err := service.Register(req1)
+	_, err := service.Register(t.Context(), req1)
 	require.NoError(t, err)
 
 	// Try to register with same username but different email - should succeed
@@ -69,7 +69,7 @@ func TestAuthService_Register_DuplicateUsername(t *testing.T) {
 		Password: "Password123!",
 	}
 
-	_, err = service.Register(req2)
+	_, err = service.Register(t.Context(), req2)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "username already exists")
 }
@@ -94,7 +94,7 @@ func TestAuthService_Register_DuplicateEmail(t *testing.T) {
 		Role:         domain.RoleUser,
 		IsActive:     true,
 	}
-	err := store.CreateUser(user)
+	err := store.CreateUser(t.Context(), user)
 	require.NoError(t, err)
 
 	// Try to register with same email
@@ -104,7 +104,7 @@ func TestAuthService_Register_DuplicateEmail(t *testing.T) {
 		Password: "Password123!",
 	}
 
-	_, err = service.Register(req)
+	_, err = service.Register(t.Context(), req)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "email already exists")
 }
@@ -126,7 +126,7 @@ func TestAuthService_Login(t *testing.T) {
 		Email:    "test@example.com",
 		Password: "Password123!",
 	}
-	_, err := service.Register(registerReq)
+	_, err := service.Register(t.Context(), registerReq)
 	require.NoError(t, err)
 
 	// Test successful login with username
@@ -135,7 +135,7 @@ func TestAuthService_Login(t *testing.T) {
 		Password: "Password123!",
 	}
 
-	response, err := service.Login(loginReq)
+	response, err := service.Login(t.Context(), loginReq)
 	require.NoError(t, err)
 	assert.Equal(t, "testuser", response.User.Username)
 	assert.Equal(t, "test@example.com", response.User.Email)
@@ -160,7 +160,7 @@ func TestAuthService_Login_WithEmail(t *testing.T) {
 		Email:    "test@example.com",
 		Password: "Password123!",
 	}
-	_, err := service.Register(registerReq)
+	_, err := service.Register(t.Context(), registerReq)
 	require.NoError(t, err)
 
 	// Test successful login with email
@@ -169,7 +169,7 @@ func TestAuthService_Login_WithEmail(t *testing.T) {
 		Password: "Password123!",
 	}
 
-	response, err := service.Login(loginReq)
+	response, err := service.Login(t.Context(), loginReq)
 	require.NoError(t, err)
 	assert.Equal(t, "testuser", response.User.Username)
 	assert.Equal(t, "test@example.com", response.User.Email)
@@ -192,7 +192,7 @@ func TestAuthService_Login_InvalidCredentials(t *testing.T) {
 		Email:    "test@example.com",
 		Password: "Password123!",
 	}
-	_, err := service.Register(registerReq)
+	_, err := service.Register(t.Context(), registerReq)
 	require.NoError(t, err)
 
 	// Test login with wrong password
@@ -201,7 +201,7 @@ func TestAuthService_Login_InvalidCredentials(t *testing.T) {
 		Password: "WrongPassword123!",
 	}
 
-	_, err = service.Login(loginReq)
+	_, err = service.Login(t.Context(), loginReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid credentials")
 }
@@ -223,7 +223,7 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 		Password: "Password123!",
 	}
 
-	_, err := service.Login(loginReq)
+	_, err := service.Login(t.Context(), loginReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid credentials")
 }
@@ -245,7 +245,7 @@ func TestAuthService_RefreshToken(t *testing.T) {
 		Email:    "test@example.com",
 		Password: "Password123!",
 	}
-	registerResponse, err := service.Register(registerReq)
+	registerResponse, err := service.Register(t.Context(), registerReq)
 	require.NoError(t, err)
 
 	// Test token refresh
@@ -256,7 +256,7 @@ func TestAuthService_RefreshToken(t *testing.T) {
 	// Wait a moment to ensure token timestamps are different (JWT uses second precision)
 	time.Sleep(1100 * time.Millisecond)
 
-	response, err := service.RefreshToken(req)
+	response, err := service.RefreshToken(t.Context(), req)
 	require.NoError(t, err)
 	assert.NotEmpty(t, response.AccessToken)
 	assert.NotEqual(t, registerResponse.AccessToken, response.AccessToken)
@@ -279,7 +279,7 @@ func TestAuthService_RefreshToken_Invalid(t *testing.T) {
 		RefreshToken: "invalid-refresh-token",
 	}
 
-	_, err := service.RefreshToken(req)
+	_, err := service.RefreshToken(t.Context(), req)
 	assert.Error(t, err)
 }
 
@@ -300,11 +300,11 @@ func TestAuthService_GetUserByID(t *testing.T) {
 		Email:    "test@example.com",
 		Password: "Password123!",
 	}
-	registerResponse, err := service.Register(registerReq)
+	registerResponse, err := service.Register(t.Context(), registerReq)
 	require.NoError(t, err)
 
 	// Test GetUserByID
-	user, err := service.GetUserByID(registerResponse.User.ID)
+	user, err := service.GetUserByID(t.Context(), registerResponse.User.ID)
 	require.NoError(t, err)
 	assert.Equal(t, "testuser", user.Username)
 	assert.Equal(t, "test@example.com", user.Email)
@@ -323,7 +323,7 @@ func TestAuthService_GetUserByID_NotFound(t *testing.T) {
 	service := NewAuthService(store, jwtManager)
 
 	// Test GetUserByID with non-existent user
-	_, err := service.GetUserByID("non-existent-id")
+	_, err := service.GetUserByID(t.Context(), "non-existent-id")
 	assert.Error(t, err)
 }
 
@@ -344,7 +344,7 @@ func TestAuthService_ChangePassword(t *testing.T) {
 		Email:    "test@example.com",
 		Password: "Password123!",
 	}
-	registerResponse, err := service.Register(registerReq)
+	registerResponse, err := service.Register(t.Context(), registerReq)
 	require.NoError(t, err)
 
 	// Test password change
@@ -354,7 +354,7 @@ func TestAuthService_ChangePassword(t *testing.T) {
 		NewPassword: "NewPassword123!",
 	}
 
-	err = service.ChangePassword(req)
+	err = service.ChangePassword(t.Context(), req)
 	require.NoError(t, err)
 
 	// Verify new password works
@@ -363,12 +363,12 @@ func TestAuthService_ChangePassword(t *testing.T) {
 		Password: "NewPassword123!",
 	}
 
-	_, err = service.Login(loginReq)
+	_, err = service.Login(t.Context(), loginReq)
 	assert.NoError(t, err)
 
 	// Verify old password doesn't work
 	loginReq.Password = "Password123!"
-	_, err = service.Login(loginReq)
+	_, err = service.Login(t.Context(), loginReq)
 	assert.Error(t, err)
 }
 
@@ -389,7 +389,7 @@ func TestAuthService_ChangePassword_WrongOldPassword(t *testing.T) {
 		Email:    "test@example.com",
 		Password: "Password123!",
 	}
-	registerResponse, err := service.Register(registerReq)
+	registerResponse, err := service.Register(t.Context(), registerReq)
 	require.NoError(t, err)
 
 	// Test password change with wrong old password
@@ -399,7 +399,7 @@ func TestAuthService_ChangePassword_WrongOldPassword(t *testing.T) {
 		NewPassword: "NewPassword123!",
 	}
 
-	err = service.ChangePassword(req)
+	err = service.ChangePassword(t.Context(), req)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid old password")
 }
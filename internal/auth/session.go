@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+var (
+	// ErrInvalidRefreshToken 刷新令牌无效、已过期或所属会话已注销
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused 出示了已被轮换掉的刷新令牌（可能已泄露），会话已作废
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+	// ErrSessionNotFound 会话不存在或不属于该用户
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// maxUserAgentLength 会话记录的 User-Agent 最大长度
+const maxUserAgentLength = 512
+
+// SessionRepository 登录会话及访问令牌黑名单存储接口
+type SessionRepository interface {
+	CreateUserSession(ctx context.Context, session *domain.UserSession) error
+	GetUserSession(ctx context.Context, id string) (*domain.UserSession, error)
+	RotateUserSession(ctx context.Context, session *domain.UserSession, previousRefreshID string) error
+	ListUserSessions(ctx context.Context, userID string) ([]*domain.UserSession, error)
+	DeleteUserSession(ctx context.Context, id string) error
+	DeleteExpiredUserSessions(ctx context.Context, now time.Time) (int64, error)
+	AddToBlacklist(ctx context.Context, jti string, ttl time.Duration) error
+	IsBlacklisted(ctx context.Context, jti string) (bool, error)
+}
+
+// SessionClient 发起登录或刷新的客户端信息
+type SessionClient struct {
+	IP        string
+	UserAgent string
+}
+
+// SessionService 登录会话服务
+//
+// 每次登录创建一个会话，刷新时轮换访问令牌和刷新令牌；出示已被轮换掉的刷新令牌时整个会话作废。
+// 注销或撤销会话时把当前访问令牌的 jti 加入黑名单，直到其自然过期。
+type SessionService struct {
+	users    UserRepository
+	sessions SessionRepository
+	tokens   *jwt.Manager
+	now      func() time.Time
+}
+
+// NewSessionService 创建登录会话服务
+func NewSessionService(users UserRepository, sessions SessionRepository, tokens *jwt.Manager) *SessionService {
+	return &SessionService{
+		users:    users,
+		sessions: sessions,
+		tokens:   tokens,
+		now:      time.Now,
+	}
+}
+
+// Start 登录成功后创建会话并签发令牌对
+func (s *SessionService) Start(ctx context.Context, user *domain.User, client SessionClient) (*jwt.TokenPair, error) {
+	pair, err := s.tokens.GenerateTokenPair(ctx, user.ID, user.Email, string(user.Tier))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	session := &domain.UserSession{
+		ID:        pair.SessionID,
+		UserID:    user.ID,
+		CreatedAt: now,
+	}
+	applyTokenPair(session, pair, client, now)
+	if err := s.sessions.CreateUserSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("create session: %w", err)
+	}
+	return pair, nil
+}
+
+// Refresh 用刷新令牌轮换令牌对，旧刷新令牌随即失效
+func (s *SessionService) Refresh(ctx context.Context, refreshToken string, client SessionClient) (*jwt.TokenPair, error) {
+	claims, err := s.tokens.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.SessionID == "" || claims.ID == "" {
+		return nil, ErrInvalidRefreshToken // 启用会话前签发的刷新令牌
+	}
+
+	session, err := s.sessions.GetUserSession(ctx, claims.SessionID)
+	if errors.Is(err, storage.ErrUserSessionNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != claims.UserID {
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.RefreshTokenID != claims.ID {
+		if err := s.revoke(ctx, session); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	// 重新读取用户，等级变化后新令牌随之更新，禁用的用户不能续期
+	user, err := s.users.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if !user.IsActive {
+		if err := s.revoke(ctx, session); err != nil {
+			return nil, err
+		}
+		return nil, ErrUserInactive
+	}
+
+	pair, err := s.tokens.GenerateSessionTokenPair(ctx, session.ID, user.ID, user.Email, string(user.Tier))
+	if err != nil {
+		return nil, err
+	}
+	applyTokenPair(session, pair, client, s.now())
+	if err := s.sessions.RotateUserSession(ctx, session, claims.ID); err != nil {
+		// 并发刷新时另一请求已完成轮换，或会话已被注销
+		if errors.Is(err, storage.ErrUserSessionRotated) || errors.Is(err, storage.ErrUserSessionNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Logout 注销访问令牌所属的会话，并吊销该访问令牌
+func (s *SessionService) Logout(ctx context.Context, claims *jwt.Claims) error {
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := s.blacklist(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+	if claims.SessionID == "" {
+		return nil
+	}
+
+	session, err := s.sessions.GetUserSession(ctx, claims.SessionID)
+	if errors.Is(err, storage.ErrUserSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if session.UserID != claims.UserID {
+		return nil
+	}
+	return s.revoke(ctx, session)
+}
+
+// List 列出用户未过期的会话（按最近使用时间倒序）
+func (s *SessionService) List(ctx context.Context, userID string) ([]*domain.UserSession, error) {
+	sessions, err := s.sessions.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	active := make([]*domain.UserSession, 0, len(sessions))
+	for _, session := range sessions {
+		if session.ExpiresAt.After(now) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+// Revoke 撤销用户的某个会话（如在其他设备上登出），会话不存在或不属于该用户时返回 ErrSessionNotFound
+func (s *SessionService) Revoke(ctx context.Context, userID, sessionID string) error {
+	session, err := s.sessions.GetUserSession(ctx, sessionID)
+	if errors.Is(err, storage.ErrUserSessionNotFound) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	return s.revoke(ctx, session)
+}
+
+// RevokeAll 撤销用户除 keepSessionID 以外的全部会话（如修改密码、删除账户后），keepSessionID 为空时全部撤销
+//
+// 应用专用密码不属于任何会话，同时递增应用密码代数使其失效（账户已删除时跳过）。
+func (s *SessionService) RevokeAll(ctx context.Context, userID, keepSessionID string) error {
+	sessions, err := s.sessions.ListUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == keepSessionID {
+			continue
+		}
+		if err := s.revoke(ctx, session); err != nil {
+			return err
+		}
+	}
+	if user, err := s.users.GetUserByID(ctx, userID); err == nil && user != nil {
+		user.AppPasswordGeneration++
+		if err := s.users.UpdateUser(ctx, user); err != nil {
+			return fmt.Errorf("reset app password: %w", err)
+		}
+	}
+	return nil
+}
+
+// IsBlacklisted 判断访问令牌是否已因注销或撤销会话被吊销
+func (s *SessionService) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
+	return s.sessions.IsBlacklisted(ctx, jti)
+}
+
+// CleanupExpired 删除刷新令牌已过期的会话，返回删除数量
+func (s *SessionService) CleanupExpired(ctx context.Context) (int64, error) {
+	return s.sessions.DeleteExpiredUserSessions(ctx, s.now())
+}
+
+// revoke 吊销会话最近签发的访问令牌并删除会话（刷新令牌随之失效）
+func (s *SessionService) revoke(ctx context.Context, session *domain.UserSession) error {
+	if session.AccessTokenID != "" {
+		if err := s.blacklist(ctx, session.AccessTokenID, session.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	if err := s.sessions.DeleteUserSession(ctx, session.ID); err != nil && !errors.Is(err, storage.ErrUserSessionNotFound) {
+		return err
+	}
+	return nil
+}
+
+// blacklist 将访问令牌 jti 加入黑名单直到令牌过期
+func (s *SessionService) blacklist(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := expiresAt.Sub(s.now())
+	if ttl <= 0 {
+		return nil
+	}
+	if err := s.sessions.AddToBlacklist(ctx, jti, ttl); err != nil {
+		return fmt.Errorf("blacklist token: %w", err)
+	}
+	return nil
+}
+
+// applyTokenPair 记录新签发的令牌和客户端信息
+func applyTokenPair(session *domain.UserSession, pair *jwt.TokenPair, client SessionClient, now time.Time) {
+	session.RefreshTokenID = pair.RefreshID
+	session.AccessTokenID = pair.AccessID
+	session.AccessExpiresAt = pair.AccessExpiresAt
+	session.ExpiresAt = pair.RefreshExpiresAt
+	session.LastUsedAt = now
+	session.IP = client.IP
+	session.UserAgent = client.UserAgent
+	if len(session.UserAgent) > maxUserAgentLength {
+		session.UserAgent = strings.ToValidUTF8(session.UserAgent[:maxUserAgentLength], "")
+	}
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestSessionService(t *testing.T) {
+	ctx := context.Background()
+	client := SessionClient{IP: "10.0.0.1", UserAgent: "test-agent"}
+
+	newFixture := func(t *testing.T) (*SessionService, *memory.Store, *domain.User) {
+		t.Helper()
+		store := memory.NewStore(time.Hour)
+		user, err := NewService(store).Register(ctx, RegisterInput{Email: "alice@example.com", Password: testPassword, Username: "alice"})
+		require.NoError(t, err)
+		tokens := jwt.NewManager("test-secret-0123456789abcdefghijklmnop", "test", 15*time.Minute, 24*time.Hour)
+		return NewSessionService(store, store, tokens), store, user
+	}
+
+	t.Run("刷新时轮换令牌，旧刷新令牌失效", func(t *testing.T) {
+		sessions, _, user := newFixture(t)
+		first, err := sessions.Start(ctx, user, client)
+		require.NoError(t, err)
+
+		second, err := sessions.Refresh(ctx, first.RefreshToken, client)
+		require.NoError(t, err)
+		assert.Equal(t, first.SessionID, second.SessionID)
+		assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+		third, err := sessions.Refresh(ctx, second.RefreshToken, client)
+		require.NoError(t, err)
+		assert.Equal(t, first.SessionID, third.SessionID)
+	})
+
+	t.Run("重放旧刷新令牌时会话作废", func(t *testing.T) {
+		sessions, store, user := newFixture(t)
+		first, err := sessions.Start(ctx, user, client)
+		require.NoError(t, err)
+		second, err := sessions.Refresh(ctx, first.RefreshToken, client)
+		require.NoError(t, err)
+
+		_, err = sessions.Refresh(ctx, first.RefreshToken, client)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		_, err = sessions.Refresh(ctx, second.RefreshToken, client)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken, "合法持有者的令牌也随会话失效")
+		revoked, err := store.IsBlacklisted(ctx, second.AccessID)
+		require.NoError(t, err)
+		assert.True(t, revoked, "会话最近签发的访问令牌被吊销")
+	})
+
+	t.Run("注销后访问令牌进入黑名单，刷新令牌失效", func(t *testing.T) {
+		sessions, store, user := newFixture(t)
+		pair, err := sessions.Start(ctx, user, client)
+		require.NoError(t, err)
+		claims, err := sessions.tokens.ValidateToken(pair.AccessToken)
+		require.NoError(t, err)
+
+		require.NoError(t, sessions.Logout(ctx, claims))
+		revoked, err := store.IsBlacklisted(ctx, pair.AccessID)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		_, err = sessions.Refresh(ctx, pair.RefreshToken, client)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("列出和撤销自己的会话", func(t *testing.T) {
+		sessions, _, user := newFixture(t)
+		laptop, err := sessions.Start(ctx, user, SessionClient{IP: "10.0.0.1", UserAgent: "laptop"})
+		require.NoError(t, err)
+		phone, err := sessions.Start(ctx, user, SessionClient{IP: "10.0.0.2", UserAgent: "phone"})
+		require.NoError(t, err)
+
+		list, err := sessions.List(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+
+		assert.ErrorIs(t, sessions.Revoke(ctx, "someone-else", phone.SessionID), ErrSessionNotFound)
+		require.NoError(t, sessions.Revoke(ctx, user.ID, phone.SessionID))
+		assert.ErrorIs(t, sessions.Revoke(ctx, user.ID, phone.SessionID), ErrSessionNotFound)
+
+		list, err = sessions.List(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, laptop.SessionID, list[0].ID)
+		assert.Equal(t, "laptop", list[0].UserAgent)
+	})
+
+	t.Run("禁用的用户不能续期", func(t *testing.T) {
+		sessions, store, user := newFixture(t)
+		pair, err := sessions.Start(ctx, user, client)
+		require.NoError(t, err)
+		user.IsActive = false
+		require.NoError(t, store.UpdateUser(ctx, user))
+
+		_, err = sessions.Refresh(ctx, pair.RefreshToken, client)
+		assert.ErrorIs(t, err, ErrUserInactive)
+		list, err := sessions.List(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("清理过期会话", func(t *testing.T) {
+		sessions, _, user := newFixture(t)
+		_, err := sessions.Start(ctx, user, client)
+		require.NoError(t, err)
+
+		sessions.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+		count, err := sessions.CleanupExpired(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+}
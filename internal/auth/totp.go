@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP 参数（RFC 6238，与 Google Authenticator 等常见应用的默认值一致）
+const (
+	TOTPDigits     = 6
+	TOTPPeriod     = 30 * time.Second
+	TOTPSkew       = 1  // 允许前后各偏差一个时间步，容忍手机时钟误差
+	totpSecretSize = 20 // 160 位密钥（RFC 4226 推荐长度）
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret 生成随机 TOTP 密钥（Base32，无填充）
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, totpSecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPURL 生成认证器应用扫码用的 otpauth:// 地址
+func TOTPURL(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(TOTPDigits)},
+		"period":    {fmt.Sprint(int(TOTPPeriod.Seconds()))},
+	}
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// TOTPCode 计算指定时间的验证码
+func TOTPCode(secret string, at time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, totpStep(at)), nil
+}
+
+// validateTOTP 校验验证码，返回匹配的时间步
+//
+// 只接受大于 lastStep 的时间步：同一验证码（及更早的验证码）用过一次后不能重放。
+func validateTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil || len(code) != TOTPDigits {
+		return 0, false
+	}
+	current := totpStep(now)
+	for step := current - TOTPSkew; step <= current+TOTPSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func totpStep(at time.Time) int64 {
+	return at.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	return totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+}
+
+// hotp RFC 4226 HOTP（HMAC-SHA1，动态截断）
+func hotp(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for range TOTPDigits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod)
+}
+
+// secretCipherPrefix 密文格式版本前缀
+const secretCipherPrefix = "v1:"
+
+// ErrSecretCipher 密文无法解密（格式错误或加密密钥不匹配）
+var ErrSecretCipher = errors.New("failed to decrypt secret")
+
+// SecretCipher 用 AES-256-GCM 加密保存在数据库中的 TOTP 密钥
+type SecretCipher struct {
+	aead cipher.AEAD
+}
+
+// NewSecretCipher 由任意长度的密钥材料（SHA-256 派生为 AES-256 密钥）创建加密器
+func NewSecretCipher(key string) (*SecretCipher, error) {
+	if key == "" {
+		return nil, errors.New("secret cipher key is empty")
+	}
+	sum := sha256.Sum256([]byte("totp-secret:" + key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretCipher{aead: aead}, nil
+}
+
+// Encrypt 加密，结果为 v1:base64(nonce || 密文)
+func (c *SecretCipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return secretCipherPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的结果
+func (c *SecretCipher) Decrypt(ciphertext string) (string, error) {
+	encoded, ok := strings.CutPrefix(ciphertext, secretCipherPrefix)
+	if !ok {
+		return "", ErrSecretCipher
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) < c.aead.NonceSize() {
+		return "", ErrSecretCipher
+	}
+	nonce, sealed := raw[:c.aead.NonceSize()], raw[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrSecretCipher
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"tempmail/backend/internal/domain"
+)
+
+var (
+	// ErrTwoFactorUnavailable 未配置两步验证密钥加密器
+	ErrTwoFactorUnavailable = errors.New("two-factor authentication is not available")
+	// ErrTwoFactorEnabled 已启用两步验证
+	ErrTwoFactorEnabled = errors.New("two-factor authentication already enabled")
+	// ErrTwoFactorNotEnabled 未启用两步验证
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication not enabled")
+	// ErrTwoFactorNotSetup 尚未生成两步验证密钥
+	ErrTwoFactorNotSetup = errors.New("two-factor authentication not set up")
+	// ErrInvalidTwoFactorCode 验证码或恢复码无效
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorRequired 需要完成两步验证才能登录
+	ErrTwoFactorRequired = errors.New("two-factor authentication required")
+)
+
+// 恢复码参数
+const (
+	RecoveryCodeCount  = 10
+	recoveryCodeLength = 10 // 字符数（不含分隔符，50 位）
+)
+
+// TwoFactorSetup 生成的待验证密钥
+type TwoFactorSetup struct {
+	Secret string // Base32 密钥（手动输入用）
+	URL    string // otpauth:// 地址（生成二维码用）
+}
+
+// TwoFactorLoginInput 两步验证登录输入
+type TwoFactorLoginInput struct {
+	UserID    string // 密码验证通过后质询令牌中的用户
+	Code      string // 6 位验证码或恢复码
+	IP        string
+	UserAgent string
+}
+
+// SetTwoFactor 设置两步验证密钥加密器和认证器中显示的签发方
+func (s *Service) SetTwoFactor(cipher *SecretCipher, issuer string) {
+	s.totpCipher = cipher
+	s.totpIssuer = issuer
+}
+
+// SetupTwoFactor 生成新的 TOTP 密钥（加密保存，VerifyTwoFactorSetup 校验通过前不生效）
+func (s *Service) SetupTwoFactor(ctx context.Context, userID string) (*TwoFactorSetup, error) {
+	if s.totpCipher == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	encrypted, err := s.totpCipher.Encrypt(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+	user.TOTPSecret = encrypted
+	user.TOTPLastStep = 0
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	return &TwoFactorSetup{Secret: secret, URL: TOTPURL(s.totpIssuer, user.Email, secret)}, nil
+}
+
+// VerifyTwoFactorSetup 用认证器生成的验证码确认密钥并启用两步验证，返回恢复码（只返回这一次）
+func (s *Service) VerifyTwoFactorSetup(ctx context.Context, userID, code string) ([]string, error) {
+	if s.totpCipher == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetup
+	}
+
+	secret, err := s.totpCipher.Decrypt(user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := validateTOTP(secret, normalizeCode(code), s.now(), user.TOTPLastStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	user.RecoveryCodes = hashes
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor 关闭两步验证（需要验证码或恢复码，错误计入登录失败次数）
+func (s *Service) DisableTwoFactor(ctx context.Context, userID, code, ip string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := s.verifySecondFactor(ctx, user, code, ip); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = nil
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// VerifyTwoFactorLogin 登录第二步：校验验证码或恢复码后完成登录
+func (s *Service) VerifyTwoFactorLogin(ctx context.Context, input TwoFactorLoginInput) (*domain.User, error) {
+	if s.guard != nil && s.guard.ipBlocked(ctx, input.IP) {
+		return nil, ErrTooManyAttempts
+	}
+	user, err := s.userRepo.GetUserByID(ctx, input.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.verifySecondFactor(ctx, user, input.Code, input.IP); err != nil {
+		return nil, err
+	}
+
+	if s.guard != nil {
+		s.loginSucceeded(ctx, user, LoginInput{IP: input.IP, UserAgent: input.UserAgent})
+	}
+	_ = s.userRepo.UpdateLastLogin(ctx, user.ID)
+	return user, nil
+}
+
+// verifySecondFactor 校验验证码或恢复码；错误与密码错误共用失败计数，达到上限时同样锁定账户
+func (s *Service) verifySecondFactor(ctx context.Context, user *domain.User, code, ip string) error {
+	if s.guard != nil {
+		if err := s.checkLock(ctx, user); err != nil {
+			return err
+		}
+		s.guard.delay(ctx, user.ID)
+	}
+
+	ok, err := s.checkTwoFactorCode(ctx, user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err := s.loginFailed(ctx, user, ip); !errors.Is(err, ErrInvalidCredentials) {
+			return err
+		}
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// checkTwoFactorCode 校验验证码（记录时间步防重放）或恢复码（用后作废），通过时保存用户
+func (s *Service) checkTwoFactorCode(ctx context.Context, user *domain.User, code string) (bool, error) {
+	if s.totpCipher == nil {
+		return false, ErrTwoFactorUnavailable
+	}
+	code = normalizeCode(code)
+
+	if len(code) == TOTPDigits {
+		secret, err := s.totpCipher.Decrypt(user.TOTPSecret)
+		if err != nil {
+			return false, err
+		}
+		step, ok := validateTOTP(secret, code, s.now(), user.TOTPLastStep)
+		if !ok {
+			return false, nil
+		}
+		user.TOTPLastStep = step
+	} else {
+		index := matchRecoveryCode(user.RecoveryCodes, code)
+		if index < 0 {
+			return false, nil
+		}
+		user.RecoveryCodes = append(user.RecoveryCodes[:index:index], user.RecoveryCodes[index+1:]...)
+	}
+
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return false, fmt.Errorf("failed to update user: %w", err)
+	}
+	return true, nil
+}
+
+// normalizeCode 去掉空格和连字符并转为小写（恢复码展示时带分隔符）
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+var recoveryCodeAlphabet = []byte("0123456789abcdefghjkmnpqrstvwxyz") // Crockford Base32，不含易混淆的 i、l、o
+
+// generateRecoveryCodes 生成恢复码（xxxxx-xxxxx）及其摘要
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	hashes := make([]string, 0, RecoveryCodeCount)
+	buf := make([]byte, recoveryCodeLength)
+	for range RecoveryCodeCount {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := make([]byte, recoveryCodeLength)
+		for i, b := range buf {
+			raw[i] = recoveryCodeAlphabet[b&31]
+		}
+		code := string(raw)
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte("recovery-code:" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+// matchRecoveryCode 返回匹配的恢复码摘要下标，未匹配时返回 -1
+func matchRecoveryCode(hashes []string, code string) int {
+	if len(code) != recoveryCodeLength {
+		return -1
+	}
+	hashed := []byte(hashRecoveryCode(code))
+	for i, candidate := range hashes {
+		if subtle.ConstantTimeCompare([]byte(candidate), hashed) == 1 {
+			return i
+		}
+	}
+	return -1
+}
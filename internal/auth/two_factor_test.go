@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"encoding/base32"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 附录 B 的 SHA1 测试向量（取后 6 位）
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range cases {
+		code, err := TOTPCode(secret, time.Unix(unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, "T=%d", unix)
+	}
+}
+
+func TestTOTPURL(t *testing.T) {
+	u, err := url.Parse(TOTPURL("TempMail", "alice@example.com", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/TempMail:alice@example.com", u.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	assert.Equal(t, "TempMail", u.Query().Get("issuer"))
+}
+
+func TestSecretCipher(t *testing.T) {
+	c, err := NewSecretCipher("key-one")
+	require.NoError(t, err)
+	encrypted, err := c.Encrypt("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	assert.NotContains(t, encrypted, "JBSWY3DPEHPK3PXP")
+
+	plain, err := c.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plain)
+
+	other, err := NewSecretCipher("key-two")
+	require.NoError(t, err)
+	_, err = other.Decrypt(encrypted)
+	assert.ErrorIs(t, err, ErrSecretCipher, "密钥不匹配时解密失败")
+}
+
+func TestService_TwoFactor(t *testing.T) {
+	// enroll 为测试用户启用两步验证，返回明文密钥和恢复码
+	enroll := func(t *testing.T, f *loginGuardFixture) (string, []string) {
+		t.Helper()
+		cipher, err := NewSecretCipher("test-key")
+		require.NoError(t, err)
+		f.service.SetTwoFactor(cipher, "TempMail")
+		f.service.now = func() time.Time { return f.now }
+
+		setup, err := f.service.SetupTwoFactor(t.Context(), f.user.ID)
+		require.NoError(t, err)
+		code, err := TOTPCode(setup.Secret, f.now)
+		require.NoError(t, err)
+		codes, err := f.service.VerifyTwoFactorSetup(t.Context(), f.user.ID, code)
+		require.NoError(t, err)
+		return setup.Secret, codes
+	}
+	verify := func(f *loginGuardFixture, code string) error {
+		_, err := f.service.VerifyTwoFactorLogin(t.Context(), TwoFactorLoginInput{UserID: f.user.ID, Code: code, IP: "10.0.0.1"})
+		return err
+	}
+
+	t.Run("验证码确认后才启用", func(t *testing.T) {
+		f := newLoginGuardFixture(t)
+		cipher, err := NewSecretCipher("test-key")
+		require.NoError(t, err)
+		f.service.SetTwoFactor(cipher, "TempMail")
+		f.service.now = func() time.Time { return f.now }
+
+		setup, err := f.service.SetupTwoFactor(t.Context(), f.user.ID)
+		require.NoError(t, err)
+		stored, err := f.store.GetUserByID(t.Context(), f.user.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.TOTPEnabled)
+		assert.NotContains(t, stored.TOTPSecret, setup.Secret, "密钥加密保存")
+
+		_, err = f.service.VerifyTwoFactorSetup(t.Context(), f.user.ID, "000000")
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+		code, err := TOTPCode(setup.Secret, f.now)
+		require.NoError(t, err)
+		codes, err := f.service.VerifyTwoFactorSetup(t.Context(), f.user.ID, code)
+		require.NoError(t, err)
+		assert.Len(t, codes, RecoveryCodeCount)
+		assert.True(t, stored.TOTPEnabled)
+		assert.NotContains(t, stored.RecoveryCodes, codes[0], "恢复码只保存摘要")
+
+		_, err = f.service.SetupTwoFactor(t.Context(), f.user.ID)
+		assert.ErrorIs(t, err, ErrTwoFactorEnabled)
+	})
+
+	t.Run("密码正确后需要第二步，验证码不能重放", func(t *testing.T) {
+		f := newLoginGuardFixture(t)
+		secret, _ := enroll(t, f)
+
+		user, err := f.service.Login(t.Context(), LoginInput{Identifier: "alice@example.com", Password: testPassword, IP: "10.0.0.1"})
+		require.NoError(t, err)
+		assert.True(t, user.TOTPEnabled, "调用方据此签发质询而不是令牌")
+
+		f.now = f.now.Add(TOTPPeriod)
+		code, err := TOTPCode(secret, f.now)
+		require.NoError(t, err)
+		require.NoError(t, verify(f, code))
+		assert.ErrorIs(t, verify(f, code), ErrInvalidTwoFactorCode, "同一验证码只能使用一次")
+	})
+
+	t.Run("恢复码只能使用一次", func(t *testing.T) {
+		f := newLoginGuardFixture(t)
+		_, codes := enroll(t, f)
+
+		require.NoError(t, verify(f, codes[0]))
+		assert.ErrorIs(t, verify(f, codes[0]), ErrInvalidTwoFactorCode)
+		stored, err := f.store.GetUserByID(t.Context(), f.user.ID)
+		require.NoError(t, err)
+		assert.Len(t, stored.RecoveryCodes, RecoveryCodeCount-1)
+	})
+
+	t.Run("验证码错误计入失败次数并锁定账户", func(t *testing.T) {
+		f := newLoginGuardFixture(t)
+		enroll(t, f)
+
+		for i := 0; i < LoginLockAfterFailures-1; i++ {
+			require.ErrorIs(t, verify(f, "000000"), ErrInvalidTwoFactorCode)
+		}
+		err := verify(f, "000000")
+		var locked *LockedError
+		assert.True(t, errors.As(err, &locked))
+	})
+
+	t.Run("关闭需要验证码", func(t *testing.T) {
+		f := newLoginGuardFixture(t)
+		_, codes := enroll(t, f)
+
+		assert.ErrorIs(t, f.service.DisableTwoFactor(t.Context(), f.user.ID, "000000", "10.0.0.1"), ErrInvalidTwoFactorCode)
+		require.NoError(t, f.service.DisableTwoFactor(t.Context(), f.user.ID, codes[1], "10.0.0.1"))
+		stored, err := f.store.GetUserByID(t.Context(), f.user.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.TOTPEnabled)
+		assert.Empty(t, stored.TOTPSecret)
+		assert.Empty(t, stored.RecoveryCodes)
+
+		assert.ErrorIs(t, f.service.DisableTwoFactor(t.Context(), f.user.ID, codes[2], "10.0.0.1"), ErrTwoFactorNotEnabled)
+	})
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// maskedValue 输出配置时替换敏感字段的值
+const maskedValue = "******"
+
+// CommandLine cmd/server 和 cmd/api 共用的命令行参数
+//
+//	server [-config tempmail.yaml]                  启动服务
+//	server [-config tempmail.yaml] config validate  校验配置后退出
+//	server [-config tempmail.yaml] config print     输出合并后的生效配置后退出
+type CommandLine struct {
+	ConfigFile string   // YAML 配置文件，默认取环境变量 TEMPMAIL_CONFIG
+	Args       []string // 标志之后的参数（子命令）
+}
+
+// ParseCommandLine 解析命令行参数，-h 时返回 flag.ErrHelp
+func ParseCommandLine(name string, args []string) (*CommandLine, error) {
+	cl := &CommandLine{}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&cl.ConfigFile, "config", os.Getenv("TEMPMAIL_CONFIG"), "YAML 配置文件路径（环境变量优先于文件中的值）")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s [-config 文件] [config validate | config print]\n", name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	cl.Args = fs.Args()
+	return cl, nil
+}
+
+// Load 加载配置（配置文件由 -config 指定）
+func (cl *CommandLine) Load() (*Config, error) {
+	return LoadFile(cl.ConfigFile)
+}
+
+// RunCommand 执行子命令，没有子命令时返回 false，调用方继续启动服务
+//
+// config validate 校验通过时输出 "configuration is valid"，否则返回全部问题；
+// config print 以 YAML 输出默认值、配置文件和环境变量合并后的配置（敏感字段打码），
+// 可直接作为配置文件模板，配置无效时同样输出并返回校验错误。
+func (cl *CommandLine) RunCommand(w io.Writer) (bool, error) {
+	if len(cl.Args) == 0 {
+		return false, nil
+	}
+	if len(cl.Args) != 2 || cl.Args[0] != "config" {
+		return true, fmt.Errorf("unknown command %q, expected \"config validate\" or \"config print\"", strings.Join(cl.Args, " "))
+	}
+
+	switch cl.Args[1] {
+	case "validate":
+		if _, err := cl.Load(); err != nil {
+			return true, err
+		}
+		fmt.Fprintln(w, "configuration is valid")
+		return true, nil
+	case "print":
+		_, loadErr := cl.Load()
+		if errors.Is(loadErr, errReadConfigFile) {
+			return true, loadErr
+		}
+		out, err := yaml.Marshal(maskSecrets(viper.AllSettings()))
+		if err != nil {
+			return true, err
+		}
+		if _, err := w.Write(out); err != nil {
+			return true, err
+		}
+		return true, loadErr
+	default:
+		return true, fmt.Errorf("unknown config command %q, expected validate or print", cl.Args[1])
+	}
+}
+
+// maskSecrets 递归替换密钥、密码和连接字符串（可能包含密码）的值
+func maskSecrets(settings map[string]any) map[string]any {
+	for key, value := range settings {
+		switch v := value.(type) {
+		case map[string]any:
+			settings[key] = maskSecrets(v)
+		default:
+			if isSecretKey(key) && fmt.Sprint(v) != "" {
+				settings[key] = maskedValue
+			}
+		}
+	}
+	return settings
+}
+
+// isSecretKey 配置项是否为敏感字段（密钥 ID 不算）
+func isSecretKey(key string) bool {
+	if strings.HasSuffix(key, "_id") {
+		return false
+	}
+	return key == "dsn" || strings.Contains(key, "secret") || strings.Contains(key, "password") || strings.HasSuffix(key, "key")
+}
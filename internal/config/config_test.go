@@ -1,11 +1,15 @@
 package config
 
 import (
+	"bytes"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoad(t *testing.T) {
@@ -55,12 +59,53 @@ func TestLoad(t *testing.T) {
 		// 验证默认值
 		assert.Equal(t, "0.0.0.0", cfg.Server.Host)
 		assert.Equal(t, 8080, cfg.Server.Port)
+		assert.Equal(t, 2*time.Minute, cfg.Server.StartupTimeout)
 		assert.Equal(t, []string{"temp.mail"}, cfg.Mailbox.AllowedDomains)
 		assert.Equal(t, time.Hour, cfg.Mailbox.DefaultTTL)
 		assert.Equal(t, 3, cfg.Mailbox.MaxPerIP)
+		assert.Equal(t, 5*time.Minute, cfg.Mailbox.EventReplayWindow)
+		assert.Equal(t, []string{"X-Test-Run-ID", "X-Correlation-ID", "List-Unsubscribe"}, cfg.Mailbox.CapturedHeaders)
 		assert.Equal(t, ":25", cfg.SMTP.BindAddr)
 		assert.Equal(t, "temp.mail", cfg.SMTP.Domain)
+		assert.Empty(t, cfg.SMTP.Outbound.RelayAddr)
+		assert.Equal(t, "starttls", cfg.SMTP.Outbound.TLS)
+		assert.Equal(t, 30*time.Second, cfg.SMTP.Outbound.Timeout)
+		assert.Equal(t, 10, cfg.SMTP.Outbound.MaxRecipients)
+		assert.True(t, cfg.SMTP.VerifyDKIM)
+		assert.True(t, cfg.SMTP.Authentication.Enabled)
+		assert.Equal(t, "accept", cfg.SMTP.Authentication.FailureAction)
+		assert.Equal(t, 20, cfg.SMTP.Outbound.MailboxDailyLimit)
+		assert.Equal(t, 100, cfg.SMTP.Outbound.UserDailyLimit)
+		assert.False(t, cfg.SMTP.Outbound.AllowGuests)
+		assert.False(t, cfg.POP3.Enabled)
+		assert.Equal(t, ":110", cfg.POP3.BindAddr)
+		assert.Equal(t, 10*time.Minute, cfg.POP3.IdleTimeout)
+		assert.False(t, cfg.IMAP.Enabled)
+		assert.Equal(t, ":143", cfg.IMAP.BindAddr)
+		assert.Equal(t, 30*time.Minute, cfg.IMAP.IdleTimeout)
+		assert.False(t, cfg.GRPC.Enabled)
+		assert.Equal(t, ":9090", cfg.GRPC.BindAddr)
+		assert.Empty(t, cfg.OAuth.CallbackBaseURL)
+		assert.Empty(t, cfg.OAuth.Google.ClientID)
+		assert.Empty(t, cfg.OAuth.GitHub.ClientID)
+		assert.Equal(t, "oidc", cfg.OAuth.OIDC.Name)
+		assert.Equal(t, []string{"openid", "email", "profile"}, cfg.OAuth.OIDC.Scopes)
+		assert.Empty(t, cfg.TwoFactor.EncryptionKey)
+		assert.Equal(t, "TempMail", cfg.TwoFactor.Issuer)
 		assert.Equal(t, []string{"*"}, cfg.CORS.AllowedOrigins)
+		assert.Equal(t, 256, cfg.WebSocket.SendBuffer)
+		assert.Equal(t, 50, cfg.WebSocket.MaxDroppedEvents)
+		assert.Equal(t, 30*time.Second, cfg.WebSocket.MaxBlocked)
+		assert.Equal(t, 500, cfg.Jobs.BatchSize)
+		assert.Equal(t, 4, cfg.Jobs.Concurrency)
+		assert.Equal(t, float64(500), cfg.Jobs.RateLimit)
+		assert.True(t, cfg.Analytics.Enabled)
+		assert.False(t, cfg.Analytics.Anonymize)
+		assert.Equal(t, 400*24*time.Hour, cfg.Analytics.Retention)
+		assert.Equal(t, time.Minute, cfg.Analytics.FlushInterval)
+		assert.True(t, cfg.MailFlow.Enabled)
+		assert.Equal(t, 7*24*time.Hour, cfg.MailFlow.Retention)
+		assert.Equal(t, 10*time.Second, cfg.MailFlow.FlushInterval)
 		assert.Equal(t, "info", cfg.Log.Level)
 		assert.False(t, cfg.Log.Development)
 		assert.Equal(t, "test-secret-key-for-development-32-chars-long-at-least", cfg.JWT.Secret)
@@ -148,6 +193,49 @@ func TestLoad(t *testing.T) {
 		assert.Nil(t, cfg)
 		assert.Contains(t, err.Error(), "mailbox.allowed_domains must not be empty")
 	})
+
+	t.Run("自定义OIDC缺少签发者失败", func(t *testing.T) {
+		for _, key := range envKeys {
+			os.Unsetenv(key)
+		}
+		os.Setenv("TEMPMAIL_JWT_SECRET", "valid-jwt-secret-key-32-chars-long-minimum")
+		t.Setenv("TEMPMAIL_OAUTH_OIDC_CLIENT_ID", "tempmail")
+		t.Setenv("TEMPMAIL_OAUTH_CALLBACK_BASE_URL", "https://api.example.com")
+
+		cfg, err := Load()
+
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+		assert.Contains(t, err.Error(), "oauth.oidc.issuer_url is required")
+	})
+
+	t.Run("启用第三方登录缺少回调地址失败", func(t *testing.T) {
+		for _, key := range envKeys {
+			os.Unsetenv(key)
+		}
+		os.Setenv("TEMPMAIL_JWT_SECRET", "valid-jwt-secret-key-32-chars-long-minimum")
+		t.Setenv("TEMPMAIL_OAUTH_GITHUB_CLIENT_ID", "tempmail")
+
+		cfg, err := Load()
+
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+		assert.Contains(t, err.Error(), "oauth.callback_base_url is required")
+	})
+
+	t.Run("两步验证加密密钥太短失败", func(t *testing.T) {
+		for _, key := range envKeys {
+			os.Unsetenv(key)
+		}
+		os.Setenv("TEMPMAIL_JWT_SECRET", "valid-jwt-secret-key-32-chars-long-minimum")
+		t.Setenv("TEMPMAIL_TWO_FACTOR_ENCRYPTION_KEY", "short-key")
+
+		cfg, err := Load()
+
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+		assert.Contains(t, err.Error(), "two-factor encryption key must be at least 32 characters long")
+	})
 }
 
 func TestParseDomains(t *testing.T) {
@@ -256,6 +344,7 @@ func TestDatabaseConfig(t *testing.T) {
 		"TEMPMAIL_DATABASE_MAX_OPEN_CONNS",
 		"TEMPMAIL_DATABASE_MAX_IDLE_CONNS",
 		"TEMPMAIL_DATABASE_CONN_MAX_LIFETIME",
+		"TEMPMAIL_DATABASE_SLOW_QUERY_THRESHOLD",
 		"TEMPMAIL_REDIS_ADDRESS",
 		"TEMPMAIL_REDIS_PASSWORD",
 		"TEMPMAIL_REDIS_DB",
@@ -296,5 +385,147 @@ func TestDatabaseConfig(t *testing.T) {
 		assert.Equal(t, "localhost:6379", cfg.Redis.Address)
 		assert.Equal(t, "redis-password", cfg.Redis.Password)
 		assert.Equal(t, 1, cfg.Redis.DB)
+		assert.Equal(t, 200*time.Millisecond, cfg.Database.SlowQueryThreshold, "默认慢调用阈值")
+	})
+
+	t.Run("自定义慢调用阈值", func(t *testing.T) {
+		os.Setenv("TEMPMAIL_JWT_SECRET", "valid-jwt-secret-key-32-chars-long-minimum")
+		os.Setenv("TEMPMAIL_DATABASE_SLOW_QUERY_THRESHOLD", "50ms")
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, 50*time.Millisecond, cfg.Database.SlowQueryThreshold)
+	})
+}
+// clearEnv 清除 TEMPMAIL_ 环境变量，测试结束后恢复
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, kv := range os.Environ() {
+		if key, _, _ := strings.Cut(kv, "="); strings.HasPrefix(key, "TEMPMAIL_") {
+			t.Setenv(key, "")
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	clearEnv(t)
+	const secret = "file-jwt-secret-key-32-chars-long-minimum"
+	writeConfig := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "tempmail.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	t.Run("配置文件与环境变量分层，环境变量优先", func(t *testing.T) {
+		path := writeConfig(t, `
+server:
+  port: 9000
+mailbox:
+  allowed_domains: [Mail.Example, other.example]
+  default_ttl: 2h
+smtp:
+  drain_timeout: 1m
+jwt:
+  secret: `+secret+`
+  refresh_expiry: 14d
+cors:
+  allowed_origins:
+    - https://app.example.com
+`)
+		t.Setenv("TEMPMAIL_SERVER_PORT", "9100")
+
+		cfg, err := LoadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, 9100, cfg.Server.Port)
+		assert.Equal(t, []string{"mail.example", "other.example"}, cfg.Mailbox.AllowedDomains)
+		assert.Equal(t, 2*time.Hour, cfg.Mailbox.DefaultTTL)
+		assert.Equal(t, time.Minute, cfg.SMTP.DrainTimeout)
+		assert.Equal(t, 14*24*time.Hour, cfg.JWT.RefreshExpiry)
+		assert.Equal(t, []string{"https://app.example.com"}, cfg.CORS.AllowedOrigins)
+		assert.Equal(t, secret, cfg.JWT.Secret)
+
+		// 不带配置文件再次加载时不残留文件中的值
+		t.Setenv("TEMPMAIL_JWT_SECRET", secret)
+		cfg, err = Load()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"temp.mail"}, cfg.Mailbox.AllowedDomains)
+	})
+
+	t.Run("配置文件不存在", func(t *testing.T) {
+		_, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.ErrorIs(t, err, errReadConfigFile)
+	})
+
+	t.Run("一次列出全部校验问题", func(t *testing.T) {
+		path := writeConfig(t, `
+mailbox:
+  default_ttl: 1 hour
+smtp:
+  drain_timeout: 30
+cors:
+  allowed_origins: "https://app.example.com/path,app.example.com"
+`)
+		_, err := LoadFile(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "JWT secret is required outside development mode")
+		assert.Contains(t, err.Error(), "invalid mailbox.default_ttl")
+		assert.Contains(t, err.Error(), "invalid smtp.drain_timeout")
+		assert.Contains(t, err.Error(), `"https://app.example.com/path"`)
+		assert.Contains(t, err.Error(), `"app.example.com"`)
+	})
+
+	t.Run("开发模式未设置JWT密钥时使用随机密钥", func(t *testing.T) {
+		t.Setenv("TEMPMAIL_LOG_DEVELOPMENT", "true")
+		cfg, err := LoadFile("")
+		require.NoError(t, err)
+		assert.Len(t, cfg.JWT.Secret, 64)
+	})
+}
+
+func TestCommandLine(t *testing.T) {
+	clearEnv(t)
+	path := filepath.Join(t.TempDir(), "tempmail.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("jwt:\n  secret: cli-jwt-secret-key-32-chars-long-minimum\nredis:\n  password: hunter2\n"), 0o600))
+
+	run := func(args ...string) (string, bool, error) {
+		cl, err := ParseCommandLine("server", args)
+		require.NoError(t, err)
+		var out bytes.Buffer
+		handled, err := cl.RunCommand(&out)
+		return out.String(), handled, err
+	}
+
+	t.Run("没有子命令时继续启动", func(t *testing.T) {
+		_, handled, err := run("-config", path)
+		assert.False(t, handled)
+		assert.NoError(t, err)
 	})
-}
\ No newline at end of file
+
+	t.Run("config validate", func(t *testing.T) {
+		out, handled, err := run("-config", path, "config", "validate")
+		assert.True(t, handled)
+		require.NoError(t, err)
+		assert.Equal(t, "configuration is valid\n", out)
+
+		_, _, err = run("config", "validate")
+		assert.ErrorContains(t, err, "JWT secret is required")
+	})
+
+	t.Run("config print 输出合并后的配置并隐藏敏感字段", func(t *testing.T) {
+		out, _, err := run("-config", path, "config", "print")
+		require.NoError(t, err)
+		assert.Contains(t, out, "drain_timeout: 30s")
+		assert.Contains(t, out, "secret: '******'")
+		assert.Contains(t, out, "password: '******'")
+		assert.NotContains(t, out, "hunter2")
+		assert.NotContains(t, out, "cli-jwt-secret")
+	})
+
+	t.Run("未知子命令", func(t *testing.T) {
+		_, handled, err := run("serve")
+		assert.True(t, handled)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// durationKeys 时长类配置项，加载时逐一检查格式
+var durationKeys = []string{
+	"server.startup_timeout",
+	"mailbox.default_ttl",
+	"mailbox.domain_grace_period",
+	"mailbox.event_replay_window",
+	"smtp.drain_timeout",
+	"smtp.outbound.timeout",
+	"pop3.idle_timeout",
+	"imap.idle_timeout",
+	"websocket.max_blocked",
+	"analytics.retention",
+	"analytics.flush_interval",
+	"mailflow.retention",
+	"mailflow.flush_interval",
+	"database.conn_max_lifetime",
+	"database.slow_query_threshold",
+	"jwt.access_expiry",
+	"jwt.refresh_expiry",
+	"storage.snapshot_interval",
+	"translate.timeout",
+	"spam.timeout",
+	"scan.timeout",
+	"render.proxy_timeout",
+}
+
+// getDuration 读取时长配置，除 time.ParseDuration 的格式外还支持按天（如 "7d"）
+func getDuration(key string) (time.Duration, error) {
+	value := strings.TrimSpace(viper.GetString(key))
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	return time.ParseDuration(value)
+}
+
+// validateDurations 检查全部时长配置的格式
+//
+// 超出取值范围的值（如负数）由各配置项回退到默认值，格式错误则拒绝启动，避免拼写错误被静默忽略。
+func validateDurations() []error {
+	var problems []error
+	for _, key := range durationKeys {
+		if _, err := getDuration(key); err != nil {
+			problems = append(problems, fmt.Errorf("invalid %s: %w", key, err))
+		}
+	}
+	return problems
+}
+
+// validateCORSOrigins 检查跨域来源：只能是 "*" 或不带路径的 http(s) 来源，如 https://app.example.com
+func validateCORSOrigins(origins []string) []error {
+	var problems []error
+	for _, origin := range origins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			problems = append(problems, fmt.Errorf("invalid cors.allowed_origins entry %q: must be \"*\" or an http(s) origin such as https://app.example.com", origin))
+		}
+	}
+	return problems
+}
+
+// listValue 读取列表配置：YAML 中可以写成数组，环境变量和 .env 中为逗号分隔的字符串
+func listValue(key string) string {
+	if items, ok := viper.Get(key).([]any); ok {
+		parts := make([]string, 0, len(items))
+		for _, item := range items {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, ",")
+	}
+	return viper.GetString(key)
+}
+
+// randomSecret 生成随机的 JWT 密钥（仅开发模式未配置时使用）
+func randomSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,207 @@
+// Package devmail 开发模式下合成测试邮件：按字段构造 MIME 邮件、内置示例邮件模板和随机邮件生成。
+package devmail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoRecipient 缺少收件人
+var ErrNoRecipient = errors.New("recipient is required")
+
+// Attachment 邮件附件
+type Attachment struct {
+	Filename    string
+	ContentType string // 为空时按扩展名推断
+	Content     []byte
+}
+
+// Draft 待合成的邮件
+type Draft struct {
+	From        string
+	To          string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+	Date        time.Time         // 为零值时使用当前时间
+	Headers     map[string]string // 额外的邮件头
+}
+
+// Build 将邮件草稿合成为 RFC 5322 原始邮件。
+//
+// 同时有文本和 HTML 时生成 multipart/alternative，带附件时外层再包一层 multipart/mixed。
+func Build(d Draft) ([]byte, error) {
+	if strings.TrimSpace(d.To) == "" {
+		return nil, ErrNoRecipient
+	}
+	date := d.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", d.From)
+	writeHeader(&buf, "To", d.To)
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", d.Subject))
+	writeHeader(&buf, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", fmt.Sprintf("<%s@devmail.local>", uuid.NewString()))
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	names := make([]string, 0, len(d.Headers))
+	for name := range d.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeHeader(&buf, textproto.CanonicalMIMEHeaderKey(name), d.Headers[name])
+	}
+
+	if len(d.Attachments) == 0 {
+		if err := writeBody(&buf, d); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	writeHeader(&buf, "Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+	buf.WriteString("\r\n")
+
+	var body bytes.Buffer
+	if err := writeBody(&body, d); err != nil {
+		return nil, err
+	}
+	header, content, _ := bytes.Cut(body.Bytes(), []byte("\r\n\r\n"))
+	part, err := mw.CreatePart(parseHeader(header))
+	if err != nil {
+		return nil, err
+	}
+	part.Write(content)
+
+	for _, attachment := range d.Attachments {
+		if err := writeAttachment(mw, attachment); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBody 写入正文部分（带 Content-Type 头和空行）
+func writeBody(buf *bytes.Buffer, d Draft) error {
+	switch {
+	case d.HTML != "" && d.Text != "":
+		mw := multipart.NewWriter(buf)
+		writeHeader(buf, "Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()}))
+		buf.WriteString("\r\n")
+		if err := writeTextPart(mw, "text/plain", d.Text); err != nil {
+			return err
+		}
+		if err := writeTextPart(mw, "text/html", d.HTML); err != nil {
+			return err
+		}
+		return mw.Close()
+	case d.HTML != "":
+		return writeSingleText(buf, "text/html", d.HTML)
+	default:
+		return writeSingleText(buf, "text/plain", d.Text)
+	}
+}
+
+func writeSingleText(buf *bytes.Buffer, mediaType, content string) error {
+	writeHeader(buf, "Content-Type", mediaType+"; charset=utf-8")
+	writeHeader(buf, "Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+	qp := quotedprintable.NewWriter(buf)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeTextPart(mw *multipart.Writer, mediaType, content string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mediaType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeAttachment(mw *multipart.Writer, attachment Attachment) error {
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = contentTypeByName(attachment.Filename)
+	}
+	filename := attachment.Filename
+	if filename == "" {
+		filename = "attachment.bin"
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"name": filename}))
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	header.Set("Content-Transfer-Encoding", "base64")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+	for len(encoded) > 76 {
+		if _, err := part.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = part.Write([]byte(encoded + "\r\n"))
+	return err
+}
+
+// contentTypeByName 按文件扩展名推断附件类型
+func contentTypeByName(filename string) string {
+	if idx := strings.LastIndex(filename, "."); idx >= 0 {
+		if contentType := mime.TypeByExtension(filename[idx:]); contentType != "" {
+			return contentType
+		}
+	}
+	return "application/octet-stream"
+}
+
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	if value == "" {
+		return
+	}
+	buf.WriteString(name + ": " + value + "\r\n")
+}
+
+// parseHeader 解析 writeBody 生成的头部（每行一个头）
+func parseHeader(raw []byte) textproto.MIMEHeader {
+	header := textproto.MIMEHeader{}
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if name, value, ok := strings.Cut(line, ": "); ok {
+			header.Set(name, value)
+		}
+	}
+	return header
+}
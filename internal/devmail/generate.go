@@ -0,0 +1,91 @@
+package devmail
+
+import (
+	"fmt"
+	"html"
+	"math/rand"
+	"strings"
+)
+
+// AttachmentRate 随机邮件带附件的比例
+const AttachmentRate = 0.2
+
+// HTMLRate 随机邮件带 HTML 正文的比例
+const HTMLRate = 0.5
+
+var loremWords = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod
+tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation
+ullamco laboris nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate velit
+esse cillum fugiat nulla pariatur excepteur sint occaecat cupidatat non proident sunt culpa qui officia
+deserunt mollit anim id est laborum`)
+
+var senderNames = []string{"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi", "ivan", "judy", "mallory", "oscar"}
+
+var senderDomains = []string{"example.com", "example.net", "example.org", "mail.example.io"}
+
+// Generate 生成 n 封发往 to 的随机邮件：lorem 主题和正文、随机发件人，部分邮件带 HTML 正文和附件。
+// 使用传入的随机源，相同种子生成相同的内容。
+func Generate(rng *rand.Rand, to string, n int) []Draft {
+	drafts := make([]Draft, 0, n)
+	for i := 0; i < n; i++ {
+		text := loremParagraphs(rng, 1+rng.Intn(4))
+		draft := Draft{
+			From:    senderNames[rng.Intn(len(senderNames))] + "@" + senderDomains[rng.Intn(len(senderDomains))],
+			To:      to,
+			Subject: capitalize(loremSentence(rng, 3+rng.Intn(6))),
+			Text:    text,
+		}
+		if rng.Float64() < HTMLRate {
+			draft.HTML = "<html><body><p>" + strings.ReplaceAll(html.EscapeString(text), "\n\n", "</p><p>") + "</p></body></html>"
+		}
+		if rng.Float64() < AttachmentRate {
+			draft.Attachments = []Attachment{randomAttachment(rng, i)}
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts
+}
+
+func randomAttachment(rng *rand.Rand, index int) Attachment {
+	if rng.Intn(2) == 0 {
+		return Attachment{
+			Filename:    fmt.Sprintf("notes-%d.txt", index+1),
+			ContentType: "text/plain",
+			Content:     []byte(loremParagraphs(rng, 2)),
+		}
+	}
+	content := make([]byte, 512+rng.Intn(4096))
+	rng.Read(content)
+	return Attachment{
+		Filename:    fmt.Sprintf("report-%d.bin", index+1),
+		ContentType: "application/octet-stream",
+		Content:     content,
+	}
+}
+
+func loremSentence(rng *rand.Rand, words int) string {
+	parts := make([]string, words)
+	for i := range parts {
+		parts[i] = loremWords[rng.Intn(len(loremWords))]
+	}
+	return strings.Join(parts, " ")
+}
+
+func loremParagraphs(rng *rand.Rand, count int) string {
+	paragraphs := make([]string, count)
+	for i := range paragraphs {
+		sentences := make([]string, 2+rng.Intn(4))
+		for j := range sentences {
+			sentences[j] = capitalize(loremSentence(rng, 6+rng.Intn(10))) + "."
+		}
+		paragraphs[i] = strings.Join(sentences, " ")
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
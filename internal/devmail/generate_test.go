@@ -0,0 +1,70 @@
+package devmail
+
+import (
+	"bytes"
+	"math/rand"
+	"net/mail"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("数量和分布", func(t *testing.T) {
+		const n = 400
+		drafts := Generate(rand.New(rand.NewSource(42)), "inbox@temp.mail", n)
+		require.Len(t, drafts, n)
+
+		senders := map[string]bool{}
+		withAttachment, withHTML := 0, 0
+		for _, draft := range drafts {
+			assert.Equal(t, "inbox@temp.mail", draft.To)
+			assert.NotEmpty(t, draft.Subject)
+			assert.NotEmpty(t, draft.Text)
+			senders[draft.From] = true
+			if len(draft.Attachments) > 0 {
+				withAttachment++
+			}
+			if draft.HTML != "" {
+				withHTML++
+			}
+		}
+		assert.Greater(t, len(senders), 20, "发件人足够分散")
+		assert.InDelta(t, AttachmentRate, float64(withAttachment)/n, 0.06)
+		assert.InDelta(t, HTMLRate, float64(withHTML)/n, 0.08)
+	})
+
+	t.Run("相同种子生成相同内容", func(t *testing.T) {
+		first := Generate(rand.New(rand.NewSource(7)), "a@temp.mail", 10)
+		second := Generate(rand.New(rand.NewSource(7)), "a@temp.mail", 10)
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("生成的邮件是合法的 MIME", func(t *testing.T) {
+		for _, draft := range Generate(rand.New(rand.NewSource(1)), "a@temp.mail", 20) {
+			raw, err := Build(draft)
+			require.NoError(t, err)
+			msg, err := mail.ReadMessage(bytes.NewReader(raw))
+			require.NoError(t, err)
+			assert.Equal(t, draft.From, msg.Header.Get("From"))
+			assert.Equal(t, "1.0", msg.Header.Get("MIME-Version"))
+		}
+	})
+}
+
+func TestRender(t *testing.T) {
+	assert.Equal(t, []string{"calendar", "newsletter", "verification"}, Templates())
+
+	raw, err := Render("verification", "", "a@temp.mail")
+	require.NoError(t, err)
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+	assert.Equal(t, "no-reply@accounts.example.com", msg.Header.Get("From"))
+	assert.Regexp(t, `^Your verification code is \d{6}$`, msg.Header.Get("Subject"))
+
+	_, err = Render("missing", "", "a@temp.mail")
+	assert.ErrorIs(t, err, ErrUnknownTemplate)
+	_, err = Render("verification", "", "")
+	assert.ErrorIs(t, err, ErrNoRecipient)
+}
@@ -0,0 +1,97 @@
+package devmail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnknownTemplate 示例邮件模板不存在
+var ErrUnknownTemplate = errors.New("unknown mail template")
+
+//go:embed fixtures/*.eml
+var fixtureFS embed.FS
+
+// templates 内置示例邮件模板（文件名去掉扩展名作为模板名）
+var templates = template.Must(template.New("").ParseFS(fixtureFS, "fixtures/*.eml"))
+
+// defaultSenders 各模板未指定发件人时使用的发件人
+var defaultSenders = map[string]string{
+	"verification": "no-reply@accounts.example.com",
+	"newsletter":   "weekly@news.example.com",
+	"calendar":     "organizer@calendar.example.com",
+}
+
+// templateData 模板可用的字段
+type templateData struct {
+	From      string
+	To        string
+	Date      string
+	MessageID string
+	Code      string    // 验证码
+	Now       time.Time // UTC
+	Start     time.Time // 日程开始时间（UTC）
+	End       time.Time
+}
+
+// Templates 返回内置示例邮件模板名（按字母序）
+func Templates() []string {
+	names := make([]string, 0)
+	for _, tmpl := range templates.Templates() {
+		if name := templateName(tmpl.Name()); name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render 渲染内置示例邮件模板，from 为空时使用模板默认发件人
+func Render(name, from, to string) ([]byte, error) {
+	if strings.TrimSpace(to) == "" {
+		return nil, ErrNoRecipient
+	}
+	tmpl := templates.Lookup(name + ".eml")
+	if tmpl == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	if from == "" {
+		from = defaultSenders[name]
+	}
+
+	now := time.Now().UTC()
+	start := now.Truncate(time.Hour).Add(24 * time.Hour)
+	data := templateData{
+		From:      from,
+		To:        to,
+		Date:      now.Format(time.RFC1123Z),
+		MessageID: uuid.NewString(),
+		Code:      fmt.Sprintf("%06d", rand.Intn(1000000)),
+		Now:       now,
+		Start:     start,
+		End:       start.Add(time.Hour),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render template %s: %w", name, err)
+	}
+	// 模板文件以 LF 换行保存，SMTP 报文使用 CRLF
+	return bytes.ReplaceAll(buf.Bytes(), []byte("\n"), []byte("\r\n")), nil
+}
+
+func templateName(file string) string {
+	if path.Ext(file) != ".eml" {
+		return ""
+	}
+	return strings.TrimSuffix(file, ".eml")
+}
@@ -0,0 +1,131 @@
+package dkim
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"hash"
+	"io"
+	"strings"
+)
+
+var errHeaderTooLarge = errors.New("dkim: header too large")
+
+// headerField 原始邮件头（折叠行合并为一个字段，换行统一为 CRLF）
+type headerField struct {
+	name string
+	raw  string
+}
+
+// readHeader 读到空行为止，返回各头字段；读完后 br 位于正文开头
+func readHeader(br *bufio.Reader) ([]headerField, error) {
+	var fields []headerField
+	var current strings.Builder
+	total := 0
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		raw := current.String()
+		name, _, _ := strings.Cut(raw, ":")
+		fields = append(fields, headerField{name: strings.TrimRight(name, " \t"), raw: raw})
+		current.Reset()
+	}
+	for {
+		line, err := br.ReadString('\n')
+		total += len(line)
+		if total > maxHeaderBytes {
+			return nil, errHeaderTooLarge
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			flush()
+			return fields, nil
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			flush()
+		}
+		current.WriteString(line)
+		current.WriteString("\r\n")
+		if err == io.EOF {
+			flush()
+			return fields, nil
+		}
+	}
+}
+
+// bodyHasher 按 simple 或 relaxed 方式规范化正文并计算摘要（RFC 6376 3.4.3、3.4.4）
+//
+// 末尾的空行先计数，遇到非空行时再补写，结束时丢弃；l= 限制只对规范化后的内容生效。
+type bodyHasher struct {
+	relaxed bool
+	limit   int64 // l=，-1 表示不限
+	written int64
+	h       hash.Hash
+	line    []byte // 尚未遇到换行的部分
+	blank   int    // 暂存的空行数
+	any     bool   // 已输出过内容
+}
+
+func newBodyHasher(relaxed bool, limit int64) *bodyHasher {
+	return &bodyHasher{relaxed: relaxed, limit: limit, h: newHash()}
+}
+
+func (b *bodyHasher) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			b.line = append(b.line, p...)
+			break
+		}
+		b.line = append(b.line, p[:i]...)
+		b.endLine()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// endLine 处理一个完整的行（不含换行符）
+func (b *bodyHasher) endLine() {
+	line := bytes.TrimSuffix(b.line, []byte("\r"))
+	if b.relaxed {
+		line = []byte(strings.TrimRight(collapseWhitespace(string(line)), " "))
+	}
+	b.line = b.line[:0]
+	if len(line) == 0 {
+		b.blank++
+		return
+	}
+	for ; b.blank > 0; b.blank-- {
+		b.emit([]byte("\r\n"))
+	}
+	b.emit(line)
+	b.emit([]byte("\r\n"))
+}
+
+func (b *bodyHasher) emit(p []byte) {
+	b.any = true
+	if b.limit >= 0 {
+		if remaining := b.limit - b.written; int64(len(p)) > remaining {
+			p = p[:max(remaining, 0)]
+		}
+	}
+	b.written += int64(len(p))
+	b.h.Write(p)
+}
+
+// sum 正文结束，返回摘要
+func (b *bodyHasher) sum() []byte {
+	if len(b.line) > 0 {
+		b.endLine()
+	}
+	// simple 方式下空正文规范化为一个 CRLF，relaxed 方式下为空
+	if !b.any && !b.relaxed {
+		b.emit([]byte("\r\n"))
+	}
+	return b.h.Sum(nil)
+}
@@ -0,0 +1,425 @@
+// Package dkim 收信时的 DKIM 签名验证（RFC 6376、RFC 8463）
+//
+// 只做验证：原始邮件流式读入，邮件头缓存在内存中，正文按各签名的规范化方式边读边计算摘要。
+package dkim
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// 验证结果（与邮件上保存的值相同）
+const (
+	ResultPass = domain.DKIMResultPass // 至少一个签名验证通过
+	ResultFail = domain.DKIMResultFail // 有签名但都未通过（签名或正文摘要不匹配、公钥不存在或已吊销等）
+	ResultNone = domain.DKIMResultNone // 没有签名，或因 DNS 临时错误无法验证
+)
+
+const (
+	// maxHeaderBytes 缓存的邮件头上限，超出时不验证
+	maxHeaderBytes = 1 << 20
+	// maxSignatures 每封邮件最多验证的签名数
+	maxSignatures = 5
+	// minRSAKeyBits 可接受的最短 RSA 公钥（RFC 8301）
+	minRSAKeyBits = 1024
+	// defaultTimeout 查询公钥的默认超时
+	defaultTimeout = 10 * time.Second
+)
+
+var (
+	errSignatureSyntax = errors.New("dkim: malformed signature")
+	errUnsupported     = errors.New("dkim: unsupported algorithm or canonicalization")
+	errKeyUnavailable  = errors.New("dkim: temporary key lookup failure")
+)
+
+// Verification 一封邮件的验证结果
+type Verification struct {
+	Result string   // ResultPass、ResultFail 或 ResultNone
+	Domain string   // 通过验证的签名域名（d=），未通过时为第一个签名的域名
+	Passed []string // 所有通过验证的签名域名（DMARC 对齐检查用）
+}
+
+// TXTLookup 查询 DNS TXT 记录（测试中替换为固定公钥）
+type TXTLookup func(ctx context.Context, name string) ([]string, error)
+
+// Verifier DKIM 验证器，可并发使用
+type Verifier struct {
+	lookup  TXTLookup
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewVerifier 创建验证器，lookup 为 nil 时使用系统 DNS
+func NewVerifier(lookup TXTLookup) *Verifier {
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupTXT
+	}
+	return &Verifier{lookup: lookup, timeout: defaultTimeout, now: time.Now}
+}
+
+// Verify 读完整封原始邮件并验证其中的 DKIM 签名
+//
+// 读取失败返回错误；邮件本身的问题（签名格式错误、公钥缺失等）体现在结果中。
+func (v *Verifier) Verify(ctx context.Context, r io.Reader) (Verification, error) {
+	br := bufio.NewReader(r)
+	fields, err := readHeader(br)
+	if err != nil {
+		if errors.Is(err, errHeaderTooLarge) {
+			_, err = io.Copy(io.Discard, br)
+			return Verification{Result: ResultNone}, err
+		}
+		return Verification{}, err
+	}
+
+	var sigs []*signature
+	var sigErrs []error
+	var firstDomain string
+	for _, field := range fields {
+		if !strings.EqualFold(field.name, "DKIM-Signature") || len(sigs)+len(sigErrs) >= maxSignatures {
+			continue
+		}
+		sig, err := parseSignature(field.raw)
+		if sig != nil && firstDomain == "" {
+			firstDomain = sig.domain
+		}
+		if err != nil {
+			sigErrs = append(sigErrs, err)
+			continue
+		}
+		sigs = append(sigs, sig)
+	}
+	if len(sigs) == 0 && len(sigErrs) == 0 {
+		_, err := io.Copy(io.Discard, br)
+		return Verification{Result: ResultNone}, err
+	}
+
+	// 正文只读一遍，同时写入各签名的规范化器
+	writers := make([]io.Writer, len(sigs))
+	for i, sig := range sigs {
+		writers[i] = sig.body
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), br); err != nil {
+		return Verification{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+	temporary := false
+	var passed []string
+	for _, sig := range sigs {
+		err := v.verifySignature(ctx, sig, fields)
+		if err == nil {
+			passed = append(passed, sig.domain)
+		} else if errors.Is(err, errKeyUnavailable) {
+			temporary = true
+		}
+	}
+	if len(passed) > 0 {
+		return Verification{Result: ResultPass, Domain: passed[0], Passed: passed}, nil
+	}
+	if temporary && len(sigErrs) == 0 {
+		// 无法确认签名无效时按未签名处理，避免把正常邮件误报为伪造
+		return Verification{Result: ResultNone, Domain: firstDomain}, nil
+	}
+	return Verification{Result: ResultFail, Domain: firstDomain}, nil
+}
+
+// verifySignature 验证单个签名：正文摘要、签名有效期、公钥和头部签名
+func (v *Verifier) verifySignature(ctx context.Context, sig *signature, fields []headerField) error {
+	bodyHash := sig.body.sum()
+	if !bytes.Equal(bodyHash, sig.bodyHash) {
+		return errors.New("dkim: body hash mismatch")
+	}
+	if sig.expires > 0 && v.now().Unix() > sig.expires {
+		return errors.New("dkim: signature expired")
+	}
+
+	key, err := v.lookupKey(ctx, sig)
+	if err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	for _, field := range selectHeaders(fields, sig.headers) {
+		h.Write([]byte(sig.canonicalHeader(field)))
+	}
+	signed := sig.canonicalHeader(headerField{name: sig.fieldName, raw: stripSignatureValue(sig.field)})
+	h.Write([]byte(strings.TrimSuffix(signed, "\r\n")))
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, sig.signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, digest, sig.signature) {
+			return errors.New("dkim: ed25519 signature mismatch")
+		}
+		return nil
+	}
+	return errUnsupported
+}
+
+// lookupKey 查询 <selector>._domainkey.<domain> 的公钥记录
+func (v *Verifier) lookupKey(ctx context.Context, sig *signature) (crypto.PublicKey, error) {
+	records, err := v.lookup(ctx, sig.selector+"._domainkey."+sig.domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, fmt.Errorf("dkim: no key for selector %q", sig.selector)
+		}
+		return nil, fmt.Errorf("%w: %v", errKeyUnavailable, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("dkim: no key for selector %q", sig.selector)
+	}
+	tags, err := parseTags(records[0])
+	if err != nil {
+		return nil, err
+	}
+	if version, ok := tags["v"]; ok && version != "DKIM1" {
+		return nil, errors.New("dkim: unsupported key record version")
+	}
+	if hashes, ok := tags["h"]; ok && !containsFold(strings.Split(hashes, ":"), "sha256") {
+		return nil, errors.New("dkim: key does not permit sha256")
+	}
+	keyType := tags["k"]
+	if keyType == "" {
+		keyType = "rsa"
+	}
+	if keyType != sig.keyType {
+		return nil, errors.New("dkim: key type does not match signature algorithm")
+	}
+	data, err := base64.StdEncoding.DecodeString(stripWhitespace(tags["p"]))
+	if err != nil {
+		return nil, errors.New("dkim: malformed public key")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("dkim: key revoked")
+	}
+
+	if keyType == "ed25519" {
+		if len(data) != ed25519.PublicKeySize {
+			return nil, errors.New("dkim: malformed ed25519 key")
+		}
+		return ed25519.PublicKey(data), nil
+	}
+	var pub *rsa.PublicKey
+	if parsed, err := x509.ParsePKIXPublicKey(data); err == nil {
+		pub, _ = parsed.(*rsa.PublicKey)
+	} else if parsed, err := x509.ParsePKCS1PublicKey(data); err == nil {
+		pub = parsed
+	}
+	if pub == nil {
+		return nil, errors.New("dkim: malformed rsa key")
+	}
+	if pub.N.BitLen() < minRSAKeyBits {
+		return nil, errors.New("dkim: rsa key too short")
+	}
+	return pub, nil
+}
+
+// signature 解析后的 DKIM-Signature 头
+type signature struct {
+	field     string // 原始头（含头名和 CRLF）
+	fieldName string
+	keyType   string // "rsa" 或 "ed25519"
+	domain    string
+	selector  string
+	headers   []string // h= 中的头名
+	bodyHash  []byte
+	signature []byte
+	expires   int64 // x=，0 表示不过期
+	relaxedH  bool
+	body      *bodyHasher
+}
+
+// parseSignature 解析签名头；能识别出域名时即使出错也返回签名（用于报告域名）
+func parseSignature(field string) (*signature, error) {
+	name, value, ok := strings.Cut(field, ":")
+	if !ok {
+		return nil, errSignatureSyntax
+	}
+	tags, err := parseTags(value)
+	if err != nil {
+		return nil, err
+	}
+	sig := &signature{field: field, fieldName: name, domain: strings.ToLower(tags["d"]), selector: tags["s"]}
+	if tags["v"] != "1" || sig.domain == "" || sig.selector == "" || tags["h"] == "" {
+		return sig, errSignatureSyntax
+	}
+
+	switch strings.ToLower(tags["a"]) {
+	case "rsa-sha256":
+		sig.keyType = "rsa"
+	case "ed25519-sha256":
+		sig.keyType = "ed25519"
+	default:
+		// rsa-sha1 已不安全（RFC 8301），视为无效签名
+		return sig, errUnsupported
+	}
+
+	for _, header := range strings.Split(tags["h"], ":") {
+		if header = strings.TrimSpace(header); header != "" {
+			sig.headers = append(sig.headers, header)
+		}
+	}
+	if !containsFold(sig.headers, "From") {
+		return sig, errSignatureSyntax
+	}
+	if sig.bodyHash, err = base64.StdEncoding.DecodeString(stripWhitespace(tags["bh"])); err != nil {
+		return sig, errSignatureSyntax
+	}
+	if sig.signature, err = base64.StdEncoding.DecodeString(stripWhitespace(tags["b"])); err != nil || len(sig.signature) == 0 {
+		return sig, errSignatureSyntax
+	}
+	if x, ok := tags["x"]; ok {
+		if sig.expires, err = strconv.ParseInt(x, 10, 64); err != nil {
+			return sig, errSignatureSyntax
+		}
+	}
+
+	headerCanon, bodyCanon, _ := strings.Cut(strings.ToLower(tags["c"]), "/")
+	if headerCanon == "" {
+		headerCanon = "simple"
+	}
+	if bodyCanon == "" {
+		bodyCanon = "simple"
+	}
+	if (headerCanon != "simple" && headerCanon != "relaxed") || (bodyCanon != "simple" && bodyCanon != "relaxed") {
+		return sig, errUnsupported
+	}
+	sig.relaxedH = headerCanon == "relaxed"
+
+	limit := int64(-1)
+	if l, ok := tags["l"]; ok {
+		if limit, err = strconv.ParseInt(l, 10, 64); err != nil || limit < 0 {
+			return sig, errSignatureSyntax
+		}
+	}
+	sig.body = newBodyHasher(bodyCanon == "relaxed", limit)
+	return sig, nil
+}
+
+// canonicalHeader 按签名的头规范化方式输出一个头（含 CRLF）
+func (sig *signature) canonicalHeader(field headerField) string {
+	if !sig.relaxedH {
+		return field.raw
+	}
+	name, value, _ := strings.Cut(field.raw, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	return strings.ToLower(strings.TrimRight(name, " \t")) + ":" + strings.TrimSpace(collapseWhitespace(value)) + "\r\n"
+}
+
+// parseTags 解析 tag=value 列表（值中的折叠空白保留，由调用方按需去除）
+func parseTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, errSignatureSyntax
+		}
+		name = strings.TrimSpace(name)
+		if _, dup := tags[name]; dup {
+			return nil, errSignatureSyntax
+		}
+		tags[name] = strings.TrimSpace(value)
+	}
+	return tags, nil
+}
+
+// stripSignatureValue 清空签名头中 b= 的值（计算头部摘要时使用）
+func stripSignatureValue(field string) string {
+	name, value, _ := strings.Cut(field, ":")
+	parts := strings.Split(value, ";")
+	for i, part := range parts {
+		tag, _, ok := strings.Cut(part, "=")
+		if ok && strings.TrimSpace(tag) == "b" {
+			parts[i] = part[:strings.Index(part, "=")+1]
+		}
+	}
+	value = strings.Join(parts, ";")
+	if !strings.HasSuffix(value, "\r\n") {
+		value += "\r\n"
+	}
+	return name + ":" + value
+}
+
+// selectHeaders 按 h= 的顺序取被签名的头：同名头从下往上依次取，不存在的头忽略
+func selectHeaders(fields []headerField, names []string) []headerField {
+	used := make(map[int]bool)
+	var selected []headerField
+	for _, name := range names {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if !used[i] && strings.EqualFold(fields[i].name, name) {
+				used[i] = true
+				selected = append(selected, fields[i])
+				break
+			}
+		}
+	}
+	return selected
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), target) {
+			return true
+		}
+	}
+	return false
+}
+
+func stripWhitespace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// collapseWhitespace 把连续的空格和制表符替换为一个空格
+func collapseWhitespace(s string) string {
+	var b strings.Builder
+	space := false
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == ' ' || c == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteByte(s[i])
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// newHash 签名使用的摘要算法（目前只支持 SHA-256）
+func newHash() hash.Hash {
+	return sha256.New()
+}
@@ -0,0 +1,148 @@
+package dkim
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 8463 附录 A 的示例邮件（同时带 Ed25519 和 RSA 签名）
+const rfc8463Message = "DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;\r\n" +
+	" d=football.example.com; i=@football.example.com;\r\n" +
+	" q=dns/txt; s=brisbane; t=1528637909; h=from : to :\r\n" +
+	" subject : date : message-id : from : subject : date;\r\n" +
+	" bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;\r\n" +
+	" b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus\r\n" +
+	" Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==\r\n" +
+	"DKIM-Signature: v=1; a=rsa-sha256; c=relaxed/relaxed;\r\n" +
+	" d=football.example.com; i=@football.example.com;\r\n" +
+	" q=dns/txt; s=test; t=1528637909; h=from : to : subject :\r\n" +
+	" date : message-id : from : subject : date;\r\n" +
+	" bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;\r\n" +
+	" b=F45dVWDfMbQDGHJFlXUNB2HKfbCeLRyhDXgFpEL8GwpsRe0IeIixNTe3\r\n" +
+	" DhCVlUrSjV4BwcVcOF6+FF3Zo9Rpo1tFOeS9mPYQTnGdaSGsgeefOsk2Jz\r\n" +
+	" dA+L10TeYt9BgDfQNZtKdN1WO//KgIqXP7OdEFE4LjFYNcUxZQ4FADY+8=\r\n" +
+	"From: Joe SixPack <joe@football.example.com>\r\n" +
+	"To: Suzie Q <suzie@shopping.example.net>\r\n" +
+	"Subject: Is dinner ready?\r\n" +
+	"Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)\r\n" +
+	"Message-ID: <20030712040037.46341.5F8J@football.example.com>\r\n" +
+	"\r\n" +
+	"Hi.\r\n" +
+	"\r\n" +
+	"We lost the game.  Are you hungry yet?\r\n" +
+	"\r\n" +
+	"Joe.\r\n"
+
+var rfc8463Keys = map[string]string{
+	"brisbane._domainkey.football.example.com": "v=DKIM1; k=ed25519; p=11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=",
+	"test._domainkey.football.example.com":     "v=DKIM1; k=rsa; p=MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQDkHlOQoBTzWRiGs5V6NpP3idY6Wk08a5qhdR6wy5bdOKb2jLQiY/J16JYi0Qvx/byYzCNb3W91y3FutACDfzwQ/BC/e/8uBsCR+yz1Lxj+PL6lHvqMKrM3rG4hstT5QjvHO9PzoxZyVYLzBfO2EeC3Ip3G+2kryOTIKT+l/K4w3QIDAQAB",
+}
+
+func staticLookup(keys map[string]string) TXTLookup {
+	return func(_ context.Context, name string) ([]string, error) {
+		if record, ok := keys[name]; ok {
+			return []string{record}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+}
+
+func verify(t *testing.T, v *Verifier, message string) Verification {
+	t.Helper()
+	result, err := v.Verify(t.Context(), strings.NewReader(message))
+	require.NoError(t, err)
+	return result
+}
+
+// signSimple 以 simple/simple 规范化方式签名（独立于被测代码的最小实现）
+func signSimple(t *testing.T, key *rsa.PrivateKey, headers, body string) string {
+	t.Helper()
+	bodyHash := sha256.Sum256([]byte(body))
+	sig := "DKIM-Signature: v=1; a=rsa-sha256; c=simple/simple; d=vendor.example; s=sel;\r\n" +
+		" h=From:Subject; bh=" + base64.StdEncoding.EncodeToString(bodyHash[:]) + "; b="
+	digest := sha256.Sum256([]byte(headers + sig))
+	signed, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return sig + base64.StdEncoding.EncodeToString(signed) + "\r\n" + headers + "\r\n" + body
+}
+
+func TestVerifier_RFC8463(t *testing.T) {
+	t.Run("Ed25519 和 RSA 签名都通过", func(t *testing.T) {
+		v := NewVerifier(staticLookup(rfc8463Keys))
+		assert.Equal(t, Verification{
+			Result: ResultPass, Domain: "football.example.com", Passed: []string{"football.example.com", "football.example.com"},
+		}, verify(t, v, rfc8463Message))
+
+		// 只保留 RSA 公钥：第一个签名失败，第二个通过
+		v = NewVerifier(staticLookup(map[string]string{"test._domainkey.football.example.com": rfc8463Keys["test._domainkey.football.example.com"]}))
+		assert.Equal(t, ResultPass, verify(t, v, rfc8463Message).Result)
+	})
+
+	t.Run("换行为 LF 时同样通过", func(t *testing.T) {
+		v := NewVerifier(staticLookup(rfc8463Keys))
+		assert.Equal(t, ResultPass, verify(t, v, strings.ReplaceAll(rfc8463Message, "\r\n", "\n")).Result)
+	})
+
+	t.Run("篡改正文或头", func(t *testing.T) {
+		v := NewVerifier(staticLookup(rfc8463Keys))
+		assert.Equal(t, Verification{Result: ResultFail, Domain: "football.example.com"},
+			verify(t, v, strings.Replace(rfc8463Message, "lost", "won", 1)))
+		assert.Equal(t, ResultFail, verify(t, v, strings.Replace(rfc8463Message, "Is dinner ready?", "Wire money now", 1)).Result)
+		// relaxed 方式容忍空白变化
+		assert.Equal(t, ResultPass, verify(t, v, strings.Replace(rfc8463Message, "the game.  Are", "the game. Are", 1)).Result)
+	})
+}
+
+func TestVerifier_Results(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	keys := map[string]string{"sel._domainkey.vendor.example": "v=DKIM1; p=" + base64.StdEncoding.EncodeToString(der)}
+	headers := "From: billing@vendor.example\r\nSubject: Invoice\r\n"
+	message := signSimple(t, key, headers, "pay now\r\n")
+
+	t.Run("simple 签名通过", func(t *testing.T) {
+		v := NewVerifier(staticLookup(keys))
+		assert.Equal(t, Verification{Result: ResultPass, Domain: "vendor.example", Passed: []string{"vendor.example"}}, verify(t, v, message))
+		// simple 方式下正文末尾的空行不影响结果
+		assert.Equal(t, ResultPass, verify(t, v, message+"\r\n\r\n").Result)
+		assert.Equal(t, ResultFail, verify(t, v, strings.Replace(message, "pay now", "pay  now", 1)).Result)
+	})
+
+	t.Run("没有签名", func(t *testing.T) {
+		v := NewVerifier(staticLookup(keys))
+		assert.Equal(t, Verification{Result: ResultNone}, verify(t, v, headers+"\r\nhello\r\n"))
+	})
+
+	t.Run("公钥不存在或已吊销", func(t *testing.T) {
+		v := NewVerifier(staticLookup(nil))
+		assert.Equal(t, Verification{Result: ResultFail, Domain: "vendor.example"}, verify(t, v, message))
+		v = NewVerifier(staticLookup(map[string]string{"sel._domainkey.vendor.example": "v=DKIM1; p="}))
+		assert.Equal(t, ResultFail, verify(t, v, message).Result)
+	})
+
+	t.Run("DNS 临时错误按未验证处理", func(t *testing.T) {
+		v := NewVerifier(func(_ context.Context, name string) ([]string, error) {
+			return nil, &net.DNSError{Err: "timeout", Name: name, IsTimeout: true}
+		})
+		assert.Equal(t, Verification{Result: ResultNone, Domain: "vendor.example"}, verify(t, v, message))
+	})
+
+	t.Run("签名格式错误", func(t *testing.T) {
+		v := NewVerifier(staticLookup(keys))
+		broken := "DKIM-Signature: v=1; a=rsa-sha1; d=vendor.example; s=sel; h=From; bh=AA==; b=AA==\r\n" + headers + "\r\nhi\r\n"
+		assert.Equal(t, Verification{Result: ResultFail, Domain: "vendor.example"}, verify(t, v, broken))
+	})
+}
@@ -0,0 +1,80 @@
+package domain
+
+import "time"
+
+// AbuseCategory 滥用举报类别
+type AbuseCategory string
+
+const (
+	AbuseCategorySpam     AbuseCategory = "spam"
+	AbuseCategoryPhishing AbuseCategory = "phishing"
+	AbuseCategoryIllegal  AbuseCategory = "illegal"
+	AbuseCategoryOther    AbuseCategory = "other"
+)
+
+// Valid 是否为已知类别
+func (c AbuseCategory) Valid() bool {
+	switch c {
+	case AbuseCategorySpam, AbuseCategoryPhishing, AbuseCategoryIllegal, AbuseCategoryOther:
+		return true
+	}
+	return false
+}
+
+// AbuseReportStatus 举报处理状态：new -> triaged -> actioned/dismissed
+type AbuseReportStatus string
+
+const (
+	AbuseReportStatusNew       AbuseReportStatus = "new"
+	AbuseReportStatusTriaged   AbuseReportStatus = "triaged"
+	AbuseReportStatusActioned  AbuseReportStatus = "actioned"
+	AbuseReportStatusDismissed AbuseReportStatus = "dismissed"
+)
+
+// AbuseAction 管理员对举报采取的处置
+type AbuseAction string
+
+const (
+	AbuseActionQuarantine AbuseAction = "quarantine" // 将被举报的邮件移入隔离区
+	AbuseActionSuspend    AbuseAction = "suspend"    // 停用被举报的邮箱
+	AbuseActionDismiss    AbuseAction = "dismiss"    // 驳回举报
+)
+
+// AbuseReport 针对共享域名上收到的邮件的滥用举报
+//
+// 举报人只提交地址或邮件链接，MailboxID/MessageID 由服务端解析关联，邮件内容不会返回给举报人。
+type AbuseReport struct {
+	ID            string            `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	ReporterEmail string            `json:"reporterEmail" gorm:"type:varchar(254)"`
+	ReporterIP    string            `json:"reporterIp" gorm:"type:varchar(64)"`
+	Target        string            `json:"target" gorm:"type:varchar(512)"` // 举报人提交的地址或邮件链接
+	Category      AbuseCategory     `json:"category" gorm:"type:varchar(20)"`
+	Details       string            `json:"details" gorm:"type:text"`
+	Status        AbuseReportStatus `json:"status" gorm:"type:varchar(20);index"`
+	MailboxID     string            `json:"mailboxId,omitempty" gorm:"type:varchar(36);index"` // 解析不到邮箱时为空
+	MessageID     string            `json:"messageId,omitempty" gorm:"type:varchar(36)"`
+	// Priority 同一邮箱被多个举报人举报，需优先处理
+	Priority   bool        `json:"priority" gorm:"default:false;index"`
+	Action     AbuseAction `json:"action,omitempty" gorm:"type:varchar(20)"`
+	Note       string      `json:"note,omitempty" gorm:"type:text"` // 管理员处理备注
+	ResolvedBy string      `json:"resolvedBy,omitempty" gorm:"type:varchar(36)"`
+	CreatedAt  time.Time   `json:"createdAt" gorm:"index"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+	ResolvedAt *time.Time  `json:"resolvedAt,omitempty"`
+}
+
+// AbuseReportFilter 举报列表过滤条件（零值表示不过滤）
+type AbuseReportFilter struct {
+	Status    AbuseReportStatus
+	Category  AbuseCategory
+	MailboxID string
+	Priority  bool // 只列出优先处理的举报
+}
+
+// Matches 举报是否满足过滤条件
+func (f AbuseReportFilter) Matches(report *AbuseReport) bool {
+	return (f.Status == "" || report.Status == f.Status) &&
+		(f.Category == "" || report.Category == f.Category) &&
+		(f.MailboxID == "" || report.MailboxID == f.MailboxID) &&
+		(!f.Priority || report.Priority)
+}
@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// 使用情况统计指标（只保存汇总计数，维度中不含邮箱地址、IP 等可识别个人的数据）
+const (
+	// AnalyticsMailboxesCreatedByDomain 按域名统计的邮箱创建数
+	AnalyticsMailboxesCreatedByDomain = "mailboxes.created.domain"
+	// AnalyticsMailboxesCreatedByAuth 按创建方式（guest 游客 / user 登录用户）统计的邮箱创建数
+	AnalyticsMailboxesCreatedByAuth = "mailboxes.created.auth"
+	// AnalyticsMailboxesActive 每日活跃邮箱数（当天创建或收到邮件的邮箱，按 UTC 天记录）
+	AnalyticsMailboxesActive = "mailboxes.active"
+	// AnalyticsMessagesByDomain 按收件域名统计的收件数
+	AnalyticsMessagesByDomain = "messages.received.domain"
+	// AnalyticsMessagesBySender 按发件人统计的收件数，维度为每日轮换盐的哈希（匿名模式下不记录）
+	AnalyticsMessagesBySender = "messages.received.sender"
+	// AnalyticsFeatureUsage 按功能（search、export、webhook_created）统计的调用数
+	AnalyticsFeatureUsage = "features.used"
+	// AnalyticsWebSocketPeak WebSocket 同时在线连接数峰值
+	AnalyticsWebSocketPeak = "websocket.peak"
+)
+
+// AnalyticsMetrics 所有统计指标
+var AnalyticsMetrics = []string{
+	AnalyticsMailboxesCreatedByDomain,
+	AnalyticsMailboxesCreatedByAuth,
+	AnalyticsMailboxesActive,
+	AnalyticsMessagesByDomain,
+	AnalyticsMessagesBySender,
+	AnalyticsFeatureUsage,
+	AnalyticsWebSocketPeak,
+}
+
+// IsAnalyticsMetric 判断是否为已知的统计指标
+func IsAnalyticsMetric(metric string) bool {
+	for _, m := range AnalyticsMetrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPeakAnalyticsMetric 判断指标是否为峰值类（合并时取最大值），其余指标合并时累加
+func IsPeakAnalyticsMetric(metric string) bool {
+	return metric == AnalyticsMailboxesActive || metric == AnalyticsWebSocketPeak
+}
+
+// AnalyticsBucket 一个指标维度在一个时间桶内的汇总值
+//
+// Bucket 为 UTC 整点（每日指标为 UTC 零点）。
+type AnalyticsBucket struct {
+	Metric    string    `json:"metric" gorm:"type:varchar(64);primaryKey"`
+	Dimension string    `json:"dimension" gorm:"type:varchar(255);primaryKey"`
+	Bucket    time.Time `json:"bucket" gorm:"primaryKey;index"`
+	Value     int64     `json:"value" gorm:"default:0"`
+}
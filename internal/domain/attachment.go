@@ -1,12 +1,57 @@
 package domain
 
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrAttachmentContentUnavailable 附件既没有内存内容也无法懒加载
+var ErrAttachmentContentUnavailable = errors.New("attachment content unavailable")
+
 // Attachment 表示邮件附件。
 type Attachment struct {
 	ID          string `json:"id" gorm:"primaryKey;type:varchar(36)"`            // 附件唯一标识
 	MessageID   string `json:"messageId" gorm:"type:varchar(36);index;not null"` // 所属邮件ID
 	Filename    string `json:"filename" gorm:"type:varchar(255)"`                // 文件名
-	ContentType string `json:"contentType" gorm:"type:varchar(100)"`             // MIME类型
+	ContentType string `json:"contentType" gorm:"type:varchar(100)"`             // 发件方声明的MIME类型
 	Size        int64  `json:"size"`                                             // 大小（字节）
 	StoragePath string `json:"storagePath,omitempty" gorm:"type:varchar(500)"`   // 文件存储路径（相对路径）
-	Content     []byte `json:"-" gorm:"-"`                                       // 附件内容（不存数据库，从文件系统加载）
+	SHA256      string `json:"sha256,omitempty" gorm:"-"`                        // 内容哈希（内容寻址存储，记录在文件系统元数据中）
+	Content     []byte `json:"-" gorm:"-"`                                       // 附件内容（可选，大附件为空，通过 Open 懒加载）
+
+	DetectedContentType string `json:"detectedContentType,omitempty" gorm:"type:varchar(100)"` // 按内容魔数判断的实际类型（入库时计算）
+	ContentTypeMismatch bool   `json:"contentTypeMismatch,omitempty" gorm:"default:false"`     // 声明类型与实际类型存在安全相关的不一致
+	Infected            bool   `json:"infected,omitempty" gorm:"default:false"`                // 病毒扫描检出威胁（禁止下载）
+	Threat              string `json:"threat,omitempty" gorm:"type:varchar(255)"`              // 检出的威胁名称
+
+	opener func() (io.ReadCloser, error) // 懒加载内容（由存储层设置）
+}
+
+// SetOpener 设置附件内容的懒加载方式
+func (a *Attachment) SetOpener(open func() (io.ReadCloser, error)) {
+	a.opener = open
 }
+
+// Open 打开附件内容，调用方负责关闭。
+// 内存中有 Content 时直接返回，否则通过存储层设置的懒加载读取。
+// 内存内容和存储层返回的内容通常实现 io.Seeker，下载时可按 Range 读取。
+func (a *Attachment) Open() (io.ReadCloser, error) {
+	if a.Content != nil {
+		return contentReader{bytes.NewReader(a.Content)}, nil
+	}
+	if a.opener != nil {
+		return a.opener()
+	}
+	if a.Size == 0 {
+		return contentReader{bytes.NewReader(nil)}, nil
+	}
+	return nil, ErrAttachmentContentUnavailable
+}
+
+// contentReader 可定位的内存附件内容
+type contentReader struct {
+	*bytes.Reader
+}
+
+func (contentReader) Close() error { return nil }
@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// 审计记录的目标类型
+const (
+	AuditTargetUser   = "user"
+	AuditTargetDomain = "domain"
+	AuditTargetConfig = "config"
+)
+
+// AuditLog 管理操作审计记录（只追加，不修改）
+//
+// 每个成功的管理写操作记录一条：操作者、操作、目标及字段变更（旧值和新值）。
+type AuditLog struct {
+	ID         string        `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	ActorID    string        `json:"actorId" gorm:"type:varchar(36);index"`
+	ActorEmail string        `json:"actorEmail" gorm:"type:varchar(255)"`
+	Action     string        `json:"action" gorm:"type:varchar(128);index"` // 如 user.update、domain.delete、config.update；未命名的操作为 "方法 路由"
+	TargetType string        `json:"targetType,omitempty" gorm:"type:varchar(32);index:idx_audit_logs_target"`
+	TargetID   string        `json:"targetId,omitempty" gorm:"type:varchar(255);index:idx_audit_logs_target"`
+	Changes    []AuditChange `json:"changes,omitempty" gorm:"serializer:json;type:json"`
+	Note       string        `json:"note,omitempty" gorm:"type:varchar(512)"` // 附加说明（如代为登录的原因）
+	IP         string        `json:"ip" gorm:"type:varchar(45)"`
+	CreatedAt  time.Time     `json:"createdAt" gorm:"index"`
+}
+
+// AuditChange 单个字段的变更（新建时 From 为空，删除时 To 为空）
+type AuditChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from,omitempty"`
+	To    any    `json:"to,omitempty"`
+}
+
+// AuditLogFilter 审计记录查询条件（按时间倒序分页）
+type AuditLogFilter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      time.Time // 零值表示不限
+	Until      time.Time // 不含，零值表示不限
+	Limit      int
+	Offset     int
+}
+
+// Matches 记录是否满足过滤条件（不含分页）
+func (f AuditLogFilter) Matches(log *AuditLog) bool {
+	return (f.ActorID == "" || log.ActorID == f.ActorID) &&
+		(f.Action == "" || log.Action == f.Action) &&
+		(f.TargetType == "" || log.TargetType == f.TargetType) &&
+		(f.TargetID == "" || log.TargetID == f.TargetID) &&
+		(f.Since.IsZero() || !log.CreatedAt.Before(f.Since)) &&
+		(f.Until.IsZero() || log.CreatedAt.Before(f.Until))
+}
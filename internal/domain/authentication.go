@@ -0,0 +1,47 @@
+package domain
+
+// SPF 检查结果（RFC 7208）
+const (
+	SPFResultPass      = "pass"
+	SPFResultFail      = "fail"
+	SPFResultSoftFail  = "softfail"
+	SPFResultNeutral   = "neutral"
+	SPFResultNone      = "none" // 域名没有 SPF 记录
+	SPFResultTempError = "temperror"
+	SPFResultPermError = "permerror" // 记录语法错误或超出 DNS 查询次数限制
+)
+
+// DMARC 评估结果（RFC 7489）
+const (
+	DMARCResultPass      = "pass"
+	DMARCResultFail      = "fail"
+	DMARCResultNone      = "none" // From 域名没有 DMARC 记录
+	DMARCResultTempError = "temperror"
+)
+
+// 发信认证未通过时的处理方式
+const (
+	AuthActionAccept     = "accept"     // 照常投递，只在邮件上标记
+	AuthActionQuarantine = "quarantine" // 投递到隔离区
+	AuthActionReject     = "reject"     // SMTP 阶段拒收（550）
+)
+
+// AuthenticationResults 收信时的发件人认证结果（SPF、DKIM、DMARC）
+type AuthenticationResults struct {
+	SPF         string `json:"spf"`                   // SPFResultXxx
+	SPFDomain   string `json:"spfDomain,omitempty"`   // 检查 SPF 的域名（MAIL FROM 的域名，空发件人时为 HELO 名）
+	DKIM        string `json:"dkim,omitempty"`        // DKIMResultXxx（未验证 DKIM 时为空）
+	DKIMDomain  string `json:"dkimDomain,omitempty"`  // DKIM 签名域名
+	DMARC       string `json:"dmarc"`                 // DMARCResultXxx
+	DMARCPolicy string `json:"dmarcPolicy,omitempty"` // From 域名发布的策略：none、quarantine 或 reject
+	HeaderFrom  string `json:"headerFrom,omitempty"`  // From 头的域名
+	Action      string `json:"action,omitempty"`      // 未通过认证时本系统的处理（AuthActionXxx），通过时为空
+}
+
+// Failed 是否未通过认证：DMARC 失败，或没有 DMARC 记录时 SPF 明确失败
+func (r *AuthenticationResults) Failed() bool {
+	if r == nil {
+		return false
+	}
+	return r.DMARC == DMARCResultFail || (r.DMARC == DMARCResultNone && r.SPF == SPFResultFail)
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// DistributionList 分发列表：发往列表地址的邮件复制到每个成员邮箱
+//
+// 成员为邮箱ID或同一所有者的其他分发列表ID（嵌套列表），按顺序投递。
+type DistributionList struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string    `json:"userId" gorm:"type:varchar(36);index;not null"`
+	OrgID     *string   `json:"orgId,omitempty" gorm:"type:varchar(36);index"` // 所属组织（可选）
+	Name      string    `json:"name" gorm:"type:varchar(100)"`
+	Address   string    `json:"address" gorm:"type:varchar(255);uniqueIndex;not null"` // 列表地址，位于所有者控制的域名
+	Members   []string  `json:"members" gorm:"serializer:json;type:json"`              // 成员邮箱ID（有序）
+	IsActive  bool      `json:"isActive" gorm:"default:true"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// DistributionListFailure 单个成员的投递失败
+type DistributionListFailure struct {
+	MailboxID string `json:"mailboxId"`
+	Error     string `json:"error"`
+}
+
+// DistributionListDelivery 分发列表的一次投递报告
+type DistributionListDelivery struct {
+	ID        string                    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	ListID    string                    `json:"listId" gorm:"type:varchar(36);index;not null"`
+	From      string                    `json:"from" gorm:"type:varchar(255)"`
+	Subject   string                    `json:"subject" gorm:"type:varchar(500)"`
+	Delivered []string                  `json:"delivered" gorm:"serializer:json;type:json"` // 投递成功的邮箱ID
+	Failures  []DistributionListFailure `json:"failures" gorm:"serializer:json;type:json"`
+	CreatedAt time.Time                 `json:"createdAt" gorm:"index"`
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// DomainWhitelistEntry 白名单模式用户域名允许使用的邮箱前缀
+//
+// 白名单模式下只有列表中的前缀可以创建邮箱和接收邮件，条目随域名一起删除。
+type DomainWhitelistEntry struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	DomainID  string    `json:"domainId" gorm:"type:varchar(36);not null;uniqueIndex:idx_domain_whitelist_domain_local,priority:1"`
+	LocalPart string    `json:"localPart" gorm:"type:varchar(64);not null;uniqueIndex:idx_domain_whitelist_domain_local,priority:2"` // 小写邮箱前缀
+	CreatedAt time.Time `json:"createdAt"`
+}
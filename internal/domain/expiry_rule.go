@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"regexp"
+	"time"
+)
+
+// MaxExpiryRules 每个邮箱最多的邮件到期规则数
+const MaxExpiryRules = 10
+
+// ExpiryRule 邮件到期规则：入库时按顺序匹配，第一条命中的规则决定邮件的删除时间
+//
+// 例如验证码邮件 15 分钟后删除，订阅邮件保留到邮箱过期。条件为空表示不限。
+type ExpiryRule struct {
+	FromPattern    string        `json:"fromPattern,omitempty"`    // 发件人正则（不区分大小写）
+	SubjectPattern string        `json:"subjectPattern,omitempty"` // 主题正则（不区分大小写）
+	HasAttachment  *bool         `json:"hasAttachment,omitempty"`  // 是否带附件
+	TTL            time.Duration `json:"ttl"`                      // 入库后多久删除
+}
+
+// Compile 编译规则中的正则，保存规则前用于校验
+func (r ExpiryRule) Compile() (from, subject *regexp.Regexp, err error) {
+	if r.FromPattern != "" {
+		if from, err = regexp.Compile("(?i)" + r.FromPattern); err != nil {
+			return nil, nil, err
+		}
+	}
+	if r.SubjectPattern != "" {
+		if subject, err = regexp.Compile("(?i)" + r.SubjectPattern); err != nil {
+			return nil, nil, err
+		}
+	}
+	return from, subject, nil
+}
+
+// Matches 邮件是否符合规则（正则无法编译时视为不匹配）
+func (r ExpiryRule) Matches(from, subject string, hasAttachment bool) bool {
+	if r.HasAttachment != nil && *r.HasAttachment != hasAttachment {
+		return false
+	}
+	fromRe, subjectRe, err := r.Compile()
+	if err != nil {
+		return false
+	}
+	if fromRe != nil && !fromRe.MatchString(from) {
+		return false
+	}
+	return subjectRe == nil || subjectRe.MatchString(subject)
+}
+
+// MessageExpiry 按规则顺序计算邮件的删除时间，没有规则命中时返回 nil（沿用邮箱有效期）
+func MessageExpiry(rules []ExpiryRule, from, subject string, hasAttachment bool, received time.Time) *time.Time {
+	for _, rule := range rules {
+		if rule.Matches(from, subject, hasAttachment) {
+			expiresAt := received.Add(rule.TTL)
+			return &expiresAt
+		}
+	}
+	return nil
+}
+
+// ExpiredMessages 一个邮箱中到期删除的邮件（DeleteExpiredMessages 返回，用于清理内容、修正统计和通知）
+type ExpiredMessages struct {
+	MailboxID  string
+	MessageIDs []string
+	Unread     int // 其中未读的数量
+}
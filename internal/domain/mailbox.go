@@ -12,9 +12,52 @@ type Mailbox struct {
 	Domain     string     `json:"domain" gorm:"type:varchar(100);index"`
 	Token      string     `json:"token" gorm:"type:varchar(255);uniqueIndex"`
 	UserID     *string    `json:"userId,omitempty" gorm:"type:varchar(36);index"` // 关联的用户ID（可选，游客模式为nil）
+	OrgID      *string    `json:"orgId,omitempty" gorm:"type:varchar(36);index"`  // 所属组织（可选，组织成员共享）
 	CreatedAt  time.Time  `json:"createdAt"`
 	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
 	IPSource   string     `json:"-"`
 	TotalCount int        `json:"totalCount"`
 	Unread     int        `json:"unread"`
+	// 自定义垃圾邮件阈值（为空时使用系统配置，不能超过系统上限）
+	SpamQuarantineScore *float64 `json:"spamQuarantineScore,omitempty"`
+	SpamRejectScore     *float64 `json:"spamRejectScore,omitempty"`
+	// 闲置检测：最近一次被访问的时间（API 读取、WebSocket 订阅），SMTP 投递不算访问
+	LastAccessedAt *time.Time `json:"lastAccessedAt,omitempty" gorm:"index"`
+	// 被判定为闲置的时间（已发送提醒），再次访问后清空
+	IdleSince *time.Time `json:"idleSince,omitempty"`
+	// 闲置时是否缩短了有效期，以及缩短前的原始过期时间（再次访问时恢复）
+	IdleShortened         bool       `json:"-"`
+	IdleOriginalExpiresAt *time.Time `json:"-"`
+	// 公开收件箱：任何人无需令牌即可只读查看，邮件按固定短时限自动删除
+	IsPublic bool `json:"isPublic" gorm:"default:false;index"`
+	// 因滥用被管理员停用：API 读取和 SMTP 投递均被拒绝，数据保留
+	Suspended bool `json:"suspended,omitempty" gorm:"default:false"`
+	// 邮件到期规则（按顺序匹配，最多 MaxExpiryRules 条），命中的邮件单独提前删除
+	ExpiryRules []ExpiryRule `json:"expiryRules,omitempty" gorm:"serializer:json;type:json"`
+	// 自动续期：临近过期时由清理任务按默认有效期续期，直到所属用户等级的最长生存时间
+	AutoRenew bool `json:"autoRenew" gorm:"default:false"`
+}
+
+// MailboxSummary 邮箱列表摘要：Unread/TotalCount 按收件箱邮件（不含隔离区）实时统计，
+// 并附带最近一封邮件的预览，列表页无需再逐个邮箱查询
+type MailboxSummary struct {
+	Mailbox
+	StorageBytes int64           `json:"storageBytes"`          // 全部邮件（含隔离区）的大小之和
+	LastMessage  *MessagePreview `json:"lastMessage,omitempty"` // 邮箱没有邮件时为空
+}
+
+// MessagePreview 邮件预览（列表展示用）
+type MessagePreview struct {
+	ID         string    `json:"id"`
+	Subject    string    `json:"subject"`
+	From       string    `json:"from"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// LastActivityAt 最近活动时间：最近一封邮件的接收时间，没有邮件时为邮箱创建时间
+func (s MailboxSummary) LastActivityAt() time.Time {
+	if s.LastMessage != nil && s.LastMessage.ReceivedAt.After(s.CreatedAt) {
+		return s.LastMessage.ReceivedAt
+	}
+	return s.CreatedAt
 }
@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// 转发地址状态
+const (
+	ForwardStatusPending  = "pending"  // 已向目标地址发送确认码，等待确认
+	ForwardStatusVerified = "verified" // 已确认，新邮件复制一份转发到该地址
+)
+
+// MailboxForward 邮箱转发规则：收到的邮件经发信中继复制一份发往已确认的外部地址
+//
+// 目标地址须先用发往该地址的确认码确认，避免把邮件转发给不知情的第三方。记录随邮箱一起删除。
+type MailboxForward struct {
+	ID        string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	MailboxID string `json:"mailboxId" gorm:"type:varchar(36);not null;index"`
+	Address   string `json:"address" gorm:"type:varchar(255);not null"` // 目标地址（小写）
+	Status    string `json:"status" gorm:"type:varchar(20);not null"`
+	// 确认码的 SHA-256（确认后清空）、发送时间和已尝试次数
+	CodeHash       string     `json:"-" gorm:"type:varchar(64)"`
+	CodeSentAt     *time.Time `json:"codeSentAt,omitempty"`
+	VerifyAttempts int        `json:"-" gorm:"default:0"`
+	VerifiedAt     *time.Time `json:"verifiedAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// Verified 是否已确认
+func (f *MailboxForward) Verified() bool {
+	return f.Status == ForwardStatusVerified
+}
+
+// ForwardDelivery 一封邮件到一个转发地址的投递（失败时按退避重试，与 Webhook 投递相同）
+type ForwardDelivery struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	ForwardID string     `json:"forwardId" gorm:"type:varchar(36);not null;index"`
+	MailboxID string     `json:"mailboxId" gorm:"type:varchar(36);not null;index"`
+	MessageID string     `json:"messageId" gorm:"type:varchar(36);not null"`
+	Address   string     `json:"address" gorm:"type:varchar(255)"`
+	Attempts  int        `json:"attempts" gorm:"default:0"`
+	Success   bool       `json:"success" gorm:"default:false"`
+	Error     string     `json:"error,omitempty" gorm:"type:text"`
+	NextRetry *time.Time `json:"nextRetry,omitempty" gorm:"index"` // 下次投递时间，成功或放弃后为空
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// 收信流量计数的类别（管理后台流量看板，按分钟汇总）
+const (
+	// MailFlowAccepted 接收的邮件数（维度为空，一封邮件多个收件人只计一次）
+	MailFlowAccepted = "accepted"
+	// MailFlowRejected 拒收次数，维度为拒绝原因
+	MailFlowRejected = "rejected"
+	// MailFlowSenderDomain 按发件域名统计的接收邮件数
+	MailFlowSenderDomain = "sender_domain"
+	// MailFlowRecipientDomain 按收件域名统计的接收邮件数
+	MailFlowRecipientDomain = "recipient_domain"
+)
+
+// MailFlowCounter 一类收信流量在一分钟内的计数
+//
+// Bucket 为 UTC 整分钟，多个实例写入同一时间桶时累加。
+type MailFlowCounter struct {
+	Kind      string    `json:"kind" gorm:"type:varchar(32);primaryKey"`
+	Dimension string    `json:"dimension" gorm:"type:varchar(255);primaryKey"`
+	Bucket    time.Time `json:"bucket" gorm:"primaryKey;index"`
+	Count     int64     `json:"count" gorm:"default:0"`
+}
@@ -0,0 +1,63 @@
+package domain
+
+import "time"
+
+// MaintenanceJobType 维护任务类型
+type MaintenanceJobType string
+
+const (
+	// MaintenanceJobBackfillPreviews 为只有 HTML 正文的历史邮件生成纯文本（预览、搜索使用）
+	MaintenanceJobBackfillPreviews MaintenanceJobType = "backfill-previews"
+	// MaintenanceJobRecountMailboxes 按邮件重新统计邮箱的总数和未读数
+	MaintenanceJobRecountMailboxes MaintenanceJobType = "recount-mailboxes"
+)
+
+// MaintenanceJobStatus 维护任务状态：queued -> running -> succeeded/failed/cancelled
+type MaintenanceJobStatus string
+
+const (
+	MaintenanceJobQueued    MaintenanceJobStatus = "queued"
+	MaintenanceJobRunning   MaintenanceJobStatus = "running"
+	MaintenanceJobSucceeded MaintenanceJobStatus = "succeeded"
+	MaintenanceJobFailed    MaintenanceJobStatus = "failed"
+	MaintenanceJobCancelled MaintenanceJobStatus = "cancelled"
+)
+
+// MaintenanceJob 管理员触发的重建索引、回填等批量维护任务
+//
+// 任务按主键顺序分批处理数据，每批完成后保存游标，服务重启后从游标继续。
+// 同一类型同时只能有一个排队或运行中的任务：ActiveType 在任务结束前等于 Type，结束后清空，由唯一索引保证。
+type MaintenanceJob struct {
+	ID     string               `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Type   MaintenanceJobType   `json:"type" gorm:"type:varchar(50);index"`
+	Params map[string]string    `json:"params,omitempty" gorm:"serializer:json;type:json"`
+	Status MaintenanceJobStatus `json:"status" gorm:"type:varchar(20);index"`
+	// Cursor 最近一批处理完的最后一条记录的主键，下一批从它之后开始
+	Cursor string `json:"cursor,omitempty" gorm:"type:varchar(255)"`
+	// 进度：Total 为开始时统计的待扫描记录数（估算值），Processed 为已扫描数，Updated 为实际修改数
+	Total      int64      `json:"total" gorm:"default:0"`
+	Processed  int64      `json:"processed" gorm:"default:0"`
+	Updated    int64      `json:"updated" gorm:"default:0"`
+	Error      string     `json:"error,omitempty" gorm:"type:text"`
+	CreatedBy  string     `json:"createdBy,omitempty" gorm:"type:varchar(36)"`
+	ActiveType *string    `json:"-" gorm:"type:varchar(50);uniqueIndex"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"index"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Active 任务是否排队或运行中
+func (j *MaintenanceJob) Active() bool {
+	return j.Status == MaintenanceJobQueued || j.Status == MaintenanceJobRunning
+}
+
+// SyncActiveType 按状态设置 ActiveType，保存前调用
+func (j *MaintenanceJob) SyncActiveType() {
+	if j.Active() {
+		active := string(j.Type)
+		j.ActiveType = &active
+		return
+	}
+	j.ActiveType = nil
+}
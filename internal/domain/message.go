@@ -1,24 +1,121 @@
 package domain
 
-import "time"
+import (
+	"net/textproto"
+	"sort"
+	"time"
+)
 
 // Message 表示一封临时邮箱内的邮件。
 type Message struct {
 	ID         string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
-	MailboxID  string    `json:"mailboxId" gorm:"type:varchar(36);index;not null"`
+	MailboxID  string    `json:"mailboxId" gorm:"type:varchar(36);index;index:idx_messages_mailbox_received,priority:1;index:idx_messages_mailbox_seq,priority:1;not null"`
+	Seq        int64     `json:"seq" gorm:"default:0;index:idx_messages_mailbox_seq,priority:2"` // 邮箱内单调递增的入库序号，列表按序号排序
 	From       string    `json:"from" gorm:"type:varchar(255)"`
 	To         string    `json:"to" gorm:"type:varchar(255)"`
 	Subject    string    `json:"subject" gorm:"type:varchar(500)"`
 	CreatedAt  time.Time `json:"createdAt"`
 	IsRead     bool      `json:"isRead" gorm:"default:false;index"`
-	ReceivedAt time.Time `json:"receivedAt"`
+	ReceivedAt time.Time `json:"receivedAt" gorm:"index:idx_messages_mailbox_received,priority:2"`
+	Size       int64     `json:"size" gorm:"default:0"` // 邮件大小（字节，入库时计算）
 	// 文件系统存储标记
 	HasRaw  bool `json:"hasRaw" gorm:"default:false"`
 	HasHTML bool `json:"hasHtml" gorm:"default:false"`
 	HasText bool `json:"hasText" gorm:"default:false"`
+	// Text 是由 HTML 转换生成的（原邮件没有 text/plain 部分）
+	TextDerivedFromHTML bool `json:"textDerivedFromHtml" gorm:"default:false"`
+	// 入库时检测的正文语言（ISO 639-1，无法判断时为空）
+	DetectedLanguage string `json:"detectedLanguage,omitempty" gorm:"type:varchar(8);index"`
+	// 垃圾邮件评分（未启用评分或评分服务不可用时为空）
+	SpamScore   float64  `json:"spamScore" gorm:"default:0;index"`
+	SpamAction  string   `json:"spamAction,omitempty" gorm:"type:varchar(32)"` // 评分服务建议的动作
+	SpamSymbols []string `json:"spamSymbols,omitempty" gorm:"serializer:json;type:json"`
+	Quarantined bool     `json:"quarantined" gorm:"default:false;index"` // 超过软阈值，投递到隔离区
+	IsSpam      bool     `json:"isSpam" gorm:"default:false;index"`      // 评分超过收件人的软阈值（垃圾邮件判定）
+	// 收信时的 DKIM 验证结果（DKIMResultXxx，未验证时为空）及签名域名
+	DKIMResult string `json:"dkimResult,omitempty" gorm:"type:varchar(16);index"`
+	DKIMDomain string `json:"dkimDomain,omitempty" gorm:"type:varchar(255)"`
+	// 收信时的 SPF、DMARC 认证结果（未检查时为空）
+	AuthResults *AuthenticationResults `json:"authResults,omitempty" gorm:"serializer:json;type:json"`
+	// 收信时按白名单保存的头（键为规范化头名，如 X-Test-Run-Id），用于按 CI 关联 ID 等查找邮件
+	CapturedHeaders map[string]string `json:"capturedHeaders,omitempty" gorm:"serializer:json;type:json"`
+	// 入库时从正文提取的验证码和验证链接（没有识别到时为空）
+	Verification *Verification `json:"verification,omitempty" gorm:"serializer:json;type:json"`
+	// 按邮箱到期规则计算的删除时间（没有规则命中时为空，随邮箱一起过期）
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" gorm:"index"`
 	// 内容字段（不存数据库，从文件系统加载）
 	Text        string        `json:"text,omitempty" gorm:"-"`
 	HTML        string        `json:"html,omitempty" gorm:"-"`
 	Raw         string        `json:"raw,omitempty" gorm:"-"`
 	Attachments []*Attachment `json:"attachments,omitempty" gorm:"-"` // 邮件附件列表
+	// 译文缓存（按目标语言索引，保存在文件系统元数据中）
+	TranslatedBodies map[string]string `json:"translatedBodies,omitempty" gorm:"-"`
+}
+
+// DKIM 验证结果
+const (
+	DKIMResultPass = "pass" // 签名有效
+	DKIMResultFail = "fail" // 有签名但无效，发件人可能被伪造
+	DKIMResultNone = "none" // 没有签名（或无法验证）
+)
+
+// ValidDKIMResult 是否为可筛选的 DKIM 验证结果
+func ValidDKIMResult(result string) bool {
+	return result == DKIMResultPass || result == DKIMResultFail || result == DKIMResultNone
+}
+
+// MessageSequence 邮箱最近分配的邮件序号（SQL 存储在保存邮件的事务内递增，与邮箱记录分开，
+// 避免用旧的邮箱对象保存时把计数写回）
+type MessageSequence struct {
+	MailboxID string `gorm:"primaryKey;type:varchar(36)"`
+	LastSeq   int64  `gorm:"not null;default:0"`
+}
+
+// SortMessagesBySeq 按入库序号倒序（最新在前）排列邮件；序号相同（升级前的历史邮件为 0）时按创建时间和 ID
+func SortMessagesBySeq(messages []Message) {
+	sort.SliceStable(messages, func(i, j int) bool {
+		a, b := messages[i], messages[j]
+		if a.Seq != b.Seq {
+			return a.Seq > b.Seq
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+}
+
+// MaxCapturedHeaderLength 保存的头值最大长度（字节，超出部分截断）
+const MaxCapturedHeaderLength = 256
+
+// CanonicalHeaderName 规范化头名（不区分大小写匹配，X-Test-Run-ID 与 x-test-run-id 相同）
+func CanonicalHeaderName(name string) string {
+	return textproto.CanonicalMIMEHeaderKey(name)
+}
+
+// ValidHeaderName 可保存和过滤的头名称：字母、数字、连字符和下划线，最长 100 字节
+//
+// 比 RFC 5322 更严格，头名可以直接拼进 JSON 路径而无需转义。
+func ValidHeaderName(name string) bool {
+	if name == "" || len(name) > 100 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesHeaders 邮件保存的头是否与过滤条件全部精确匹配（过滤条件的键须已规范化）
+func (m *Message) MatchesHeaders(filter map[string]string) bool {
+	for name, value := range filter {
+		captured, ok := m.CapturedHeaders[name]
+		if !ok || captured != value {
+			return false
+		}
+	}
+	return true
 }
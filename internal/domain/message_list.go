@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+)
+
+// 邮件列表的排序字段
+const (
+	MessageSortReceivedAt = "receivedAt"
+	MessageSortSubject    = "subject"
+	MessageSortFrom       = "from"
+)
+
+// ValidMessageSort 是否为支持的排序字段
+func ValidMessageSort(field string) bool {
+	switch field {
+	case MessageSortReceivedAt, MessageSortSubject, MessageSortFrom:
+		return true
+	}
+	return false
+}
+
+// MessageListQuery 邮件列表查询条件（分页和排序下推到存储）
+type MessageListQuery struct {
+	MailboxID   string
+	Quarantined bool              // true 时列出隔离区中的邮件
+	UnreadOnly  bool              // 只列出未读邮件
+	IsSpam      *bool             // 是否判定为垃圾邮件
+	Headers     map[string]string // 保存的头精确匹配（键为规范化头名）
+	Sort        string            // receivedAt（默认）、subject 或 from；主题和发件人不区分大小写
+	Ascending   bool              // 默认倒序；相同值按入库顺序排列，方向与排序一致
+	Limit       int               // 每页数量，0 表示不限制
+	Offset      int
+}
+
+// MessagePage 邮件列表的一页
+type MessagePage struct {
+	Messages []Message
+	Total    int // 符合条件的邮件总数
+}
+
+// Matches 邮件是否符合过滤条件（不含分页）
+func (q MessageListQuery) Matches(m *Message) bool {
+	if m.MailboxID != q.MailboxID || m.Quarantined != q.Quarantined {
+		return false
+	}
+	if q.UnreadOnly && m.IsRead {
+		return false
+	}
+	if q.IsSpam != nil && m.IsSpam != *q.IsSpam {
+		return false
+	}
+	return m.MatchesHeaders(q.Headers)
+}
+
+// PageMessages 在内存中过滤、排序并分页（内存存储和缓存命中时使用，结果与 SQL 存储一致）
+func PageMessages(messages []Message, q MessageListQuery) *MessagePage {
+	filtered := make([]Message, 0, len(messages))
+	for i := range messages {
+		if q.Matches(&messages[i]) {
+			filtered = append(filtered, messages[i])
+		}
+	}
+	SortMessages(filtered, q.Sort, q.Ascending)
+
+	page := &MessagePage{Total: len(filtered)}
+	start := min(max(q.Offset, 0), len(filtered))
+	end := len(filtered)
+	if q.Limit > 0 {
+		end = min(start+q.Limit, end)
+	}
+	page.Messages = filtered[start:end]
+	return page
+}
+
+// SortMessages 按字段排列邮件，相同值按入库顺序（SortMessagesBySeq）排列
+func SortMessages(messages []Message, field string, ascending bool) {
+	SortMessagesBySeq(messages)
+	if ascending {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		a, b := messages[i], messages[j]
+		var cmp int
+		switch field {
+		case MessageSortSubject:
+			cmp = strings.Compare(strings.ToLower(a.Subject), strings.ToLower(b.Subject))
+		case MessageSortFrom:
+			cmp = strings.Compare(strings.ToLower(a.From), strings.ToLower(b.From))
+		default:
+			cmp = a.ReceivedAt.Compare(b.ReceivedAt)
+		}
+		if ascending {
+			return cmp < 0
+		}
+		return cmp > 0
+	})
+}
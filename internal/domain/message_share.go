@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// MessageShare 单封邮件或整个邮箱的限时分享链接
+//
+// 链接本身是签名令牌，记录保存随机 Nonce（不返回给客户端）用于单独撤销，并统计访问次数。
+// MessageID 为空时分享整个邮箱：只读查看其中的全部邮件，不暴露邮箱令牌。
+type MessageShare struct {
+	ID               string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	MailboxID        string     `json:"mailboxId" gorm:"type:varchar(36);index;not null"`
+	MessageID        string     `json:"messageId,omitempty" gorm:"type:varchar(36);index;not null"`
+	Nonce            string     `json:"-" gorm:"type:varchar(64);not null"`
+	AllowAttachments bool       `json:"allowAttachments" gorm:"default:false"`
+	Redacted         bool       `json:"redacted" gorm:"default:false"` // 展示脱敏副本而不是原文
+	Views            int64      `json:"views" gorm:"default:0"`
+	LastViewedAt     *time.Time `json:"lastViewedAt,omitempty"`
+	ExpiresAt        time.Time  `json:"expiresAt"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
+
+// Active 链接在 now 时刻是否可用（未撤销且未过期）
+func (s *MessageShare) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// MailboxWide 是否为整个邮箱的分享
+func (s *MessageShare) MailboxWide() bool {
+	return s.MessageID == ""
+}
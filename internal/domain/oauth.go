@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// OAuthIdentity 用户关联的第三方登录身份（Google、GitHub 或自定义 OIDC IdP）
+//
+// 同一提供方的同一账号（Subject）只能关联一个用户；首次登录时按已验证的邮箱关联到已有账户，
+// 没有对应账户时自动创建。
+type OAuthIdentity struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID      string     `json:"userId" gorm:"type:varchar(36);not null;index"`
+	Provider    string     `json:"provider" gorm:"type:varchar(50);not null;uniqueIndex:idx_oauth_identities_provider_subject,priority:1"`
+	Subject     string     `json:"subject" gorm:"type:varchar(255);not null;uniqueIndex:idx_oauth_identities_provider_subject,priority:2"` // 提供方的用户唯一标识（OIDC sub、GitHub 用户 ID）
+	Email       string     `json:"email" gorm:"type:varchar(255)"`                                                                         // 关联时提供方返回的邮箱
+	CreatedAt   time.Time  `json:"createdAt"`
+	LastLoginAt *time.Time `json:"lastLoginAt,omitempty"`
+}
+
+// TableName 表名（默认命名会拆成 o_auth_identities）
+func (OAuthIdentity) TableName() string {
+	return "oauth_identities"
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// OrgRole 组织成员角色
+type OrgRole string
+
+const (
+	OrgRoleOwner  OrgRole = "owner"  // 所有者：管理域名、组织设置和成员
+	OrgRoleMember OrgRole = "member" // 成员：读写组织邮箱和邮件
+)
+
+// Organization 组织/团队，成员共享组织下的邮箱、域名、Webhook 和标签
+type Organization struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Name      string    `json:"name" gorm:"type:varchar(100);not null"`
+	OwnerID   string    `json:"ownerId" gorm:"type:varchar(36);index;not null"` // 创建者，不能被移除
+	Tier      UserTier  `json:"tier" gorm:"type:varchar(20);default:'free'"`    // 组织等级（决定组织配额）
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// OrgMember 组织成员关系
+type OrgMember struct {
+	OrgID    string    `json:"orgId" gorm:"type:varchar(36);primaryKey"`
+	UserID   string    `json:"userId" gorm:"type:varchar(36);primaryKey;index"`
+	Role     OrgRole   `json:"role" gorm:"type:varchar(20);default:'member'"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// OrgInvite 组织邀请（凭加入令牌接受）
+type OrgInvite struct {
+	Token     string    `json:"token" gorm:"primaryKey;type:varchar(64)"`
+	OrgID     string    `json:"orgId" gorm:"type:varchar(36);index;not null"`
+	Email     string    `json:"email" gorm:"type:varchar(255);not null"` // 受邀邮箱，接受时必须与登录用户一致
+	InvitedBy string    `json:"invitedBy" gorm:"type:varchar(36)"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// OrgMembership 用户在某个组织中的角色（JWT 声明与授权判断使用）
+type OrgMembership struct {
+	OrgID string  `json:"orgId"`
+	Role  OrgRole `json:"role"`
+}
+
+// InOrg 判断资源是否属于组织
+func InOrg(orgID *string) bool {
+	return orgID != nil && *orgID != ""
+}
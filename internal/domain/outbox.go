@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// 发件箱事件类型
+const (
+	// OutboxEventMailReceived 新邮件入库（WebSocket 推送、mail.received Webhook 等）
+	OutboxEventMailReceived = "mail.received"
+)
+
+// OutboxEvent 事务性发件箱事件
+//
+// 与触发它的邮件在同一事务内写入，由分发器投递到各个目标后标记为已处理；
+// 进程在提交后崩溃时事件仍在表中，由任一实例的分发器补发。
+// 写入方以认领状态写入（ClaimedUntil），入库流程结束后直接投递；认领过期前其他实例不会取到该事件。
+type OutboxEvent struct {
+	ID            string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Type          string     `json:"type" gorm:"type:varchar(64)"`
+	MailboxID     string     `json:"mailboxId" gorm:"type:varchar(36);not null"`
+	MessageID     string     `json:"messageId" gorm:"type:varchar(36);not null"`
+	Delivered     []string   `json:"delivered,omitempty" gorm:"serializer:json;type:json"` // 已完成的投递目标，重试时跳过
+	Attempts      int        `json:"attempts" gorm:"default:0"`
+	LastError     string     `json:"lastError,omitempty" gorm:"type:text"`
+	ClaimToken    string     `json:"-" gorm:"type:varchar(36);index"` // 最近一次认领的令牌
+	ClaimedUntil  *time.Time `json:"claimedUntil,omitempty"`          // 认领到期时间，之前其他分发器不会取到
+	NextAttemptAt time.Time  `json:"nextAttemptAt" gorm:"index"`
+	ProcessedAt   *time.Time `json:"processedAt,omitempty" gorm:"index"` // 全部目标投递完成（或放弃）的时间
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// Claimable 事件在 now 时刻是否可以被认领（未处理、已到重试时间、没有未过期的认领）
+func (e *OutboxEvent) Claimable(now time.Time) bool {
+	return e.ProcessedAt == nil && !e.NextAttemptAt.After(now) &&
+		(e.ClaimedUntil == nil || e.ClaimedUntil.Before(now))
+}
+
+// IsDelivered 目标是否已投递完成
+func (e *OutboxEvent) IsDelivered(target string) bool {
+	return slices.Contains(e.Delivered, target)
+}
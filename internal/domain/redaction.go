@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// MessageRedaction 邮件的脱敏副本（用于分享给第三方）
+//
+// 每封邮件最多一份，重新生成时覆盖；原始邮件不受影响。记录生成时使用的规则和
+// 内置规则版本，规则升级后可据此判断是否需要重新生成。
+type MessageRedaction struct {
+	MessageID    string    `json:"messageId" gorm:"primaryKey;type:varchar(36)"`
+	MailboxID    string    `json:"mailboxId" gorm:"type:varchar(36);index;not null"`
+	RulesVersion int       `json:"rulesVersion"`
+	Rules        []string  `json:"rules" gorm:"serializer:json;type:json"`
+	Patterns     []string  `json:"patterns,omitempty" gorm:"serializer:json;type:json"` // 自定义正则
+	Subject      string    `json:"subject" gorm:"type:varchar(500)"`
+	From         string    `json:"from" gorm:"type:varchar(255)"`
+	Text         string    `json:"text" gorm:"type:text"`
+	HTML         string    `json:"html,omitempty" gorm:"type:text"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// ReservedPrefix 系统域名上保留的邮箱前缀
+//
+// 管理员添加的条目（UserID 为空）禁止任何人创建该前缀；付费用户预留的靓号前缀（UserID 非空）
+// 在有效期内只有该用户可以创建。Domain 为空时对所有系统域名生效，用户域名不受限制。
+type ReservedPrefix struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Domain    string     `json:"domain" gorm:"type:varchar(255);not null;uniqueIndex:idx_reserved_prefixes_domain_local,priority:1"`   // 小写域名，空表示所有系统域名
+	LocalPart string     `json:"localPart" gorm:"type:varchar(64);not null;uniqueIndex:idx_reserved_prefixes_domain_local,priority:2"` // 小写邮箱前缀
+	UserID    *string    `json:"userId,omitempty" gorm:"type:varchar(36);index"`                                                       // 预留给该用户，为空表示禁止使用
+	Reason    string     `json:"reason,omitempty" gorm:"type:varchar(255)"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // 为空表示永久
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Active 条目在指定时间是否仍然有效
+func (r *ReservedPrefix) Active(now time.Time) bool {
+	return r.ExpiresAt == nil || now.Before(*r.ExpiresAt)
+}
+
+// Vanity 是否为用户预留的靓号前缀
+func (r *ReservedPrefix) Vanity() bool {
+	return r.UserID != nil
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // MessageSearchCriteria 邮件搜索条件
 type MessageSearchCriteria struct {
@@ -12,8 +15,14 @@ type MessageSearchCriteria struct {
 	EndDate     *time.Time // 结束日期
 	IsRead      *bool      // 是否已读
 	HasAttachment *bool    // 是否有附件
+	Language    string     // 正文语言（ISO 639-1）
+	SpamScoreGte *float64  // 垃圾邮件评分下限（含）
+	IsSpam      *bool      // 是否判定为垃圾邮件
+	DKIMResult  string     // DKIM 验证结果（pass、fail 或 none）
+	Headers     map[string]string // 保存的头精确匹配（键为规范化头名）
 	Page        int        // 页码（默认1）
 	PageSize    int        // 每页数量（默认20，最大100）
+	Highlight   *HighlightOptions // 高亮选项（nil 表示不生成摘要）
 }
 
 // MessageSearchResult 邮件搜索结果
@@ -23,10 +32,12 @@ type MessageSearchResult struct {
 	Page       int       `json:"page"`
 	PageSize   int       `json:"pageSize"`
 	TotalPages int       `json:"totalPages"`
+	// Snippets 按邮件ID索引的命中摘要（未开启高亮时省略）
+	Snippets map[string]MessageSnippets `json:"snippets,omitempty"`
 }
 
 // MessageSearchRepository 邮件搜索仓储接口
 type MessageSearchRepository interface {
 	// SearchMessages 搜索邮件
-	SearchMessages(criteria MessageSearchCriteria) (*MessageSearchResult, error)
+	SearchMessages(ctx context.Context, criteria MessageSearchCriteria) (*MessageSearchResult, error)
 }
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"tempmail/backend/internal/htmltext"
+)
+
+const (
+	// maxIndexedTextBytes 每段正文参与索引的最大字节数（超长正文只索引开头部分）
+	maxIndexedTextBytes = 256 << 10
+	// maxIndexTermBytes 超过该长度的词项（如 base64 片段、长链接）不索引
+	maxIndexTermBytes = 64
+)
+
+// SearchDocument 邮件全文索引文档
+//
+// Terms 为主题、发件人和正文分词后去重的小写词项，以空格分隔并在首尾各带一个空格，
+// 不支持全文索引的数据库可用 LIKE '% 词 %' 按整词匹配。
+type SearchDocument struct {
+	MessageID  string    `json:"messageId" gorm:"primaryKey;type:varchar(36)"`
+	MailboxID  string    `json:"mailboxId" gorm:"type:varchar(36);index"`
+	Terms      string    `json:"-" gorm:"type:text"`
+	ReceivedAt time.Time `json:"receivedAt" gorm:"index"`
+}
+
+// TableName 指定表名
+func (SearchDocument) TableName() string {
+	return "message_search_documents"
+}
+
+// NewSearchDocument 由邮件主题、发件人和正文生成索引文档（HTML 正文转换为纯文本后索引）
+//
+// 调用方需先填充 Text 和 HTML 正文。
+func NewSearchDocument(message *Message) *SearchDocument {
+	texts := []string{message.Subject, message.From, message.Text}
+	if message.HTML != "" && !message.TextDerivedFromHTML {
+		texts = append(texts, htmltext.Render(message.HTML))
+	}
+	return &SearchDocument{
+		MessageID:  message.ID,
+		MailboxID:  message.MailboxID,
+		Terms:      " " + strings.Join(IndexTerms(texts...), " ") + " ",
+		ReceivedAt: message.ReceivedAt,
+	}
+}
+
+// IndexQuery 全文索引查询：在给定邮箱中查找命中全部词项的邮件（不含隔离区）
+type IndexQuery struct {
+	MailboxIDs []string
+	Terms      []string // 由 IndexTerms 分词的关键词
+	Page       int      // 页码（默认1）
+	PageSize   int      // 每页数量（默认20，最大100）
+}
+
+// Normalize 补全默认分页参数
+func (q *IndexQuery) Normalize() {
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = 20
+	}
+	if q.PageSize > 100 {
+		q.PageSize = 100
+	}
+}
+
+// IndexTerms 分词：按字母和数字切分并转小写，中日韩文字逐字成词；结果去重并保持出现顺序
+//
+// 建立索引和解析查询使用同一分词规则，查询的每个词项都必须命中。
+func IndexTerms(texts ...string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if term == "" || len(term) > maxIndexTermBytes || seen[term] {
+			return
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+
+	for _, text := range texts {
+		if len(text) > maxIndexedTextBytes {
+			text = text[:maxIndexedTextBytes]
+			for !utf8.ValidString(text) {
+				text = text[:len(text)-1]
+			}
+		}
+		var word strings.Builder
+		for _, r := range text {
+			switch {
+			case isIdeograph(r):
+				add(word.String())
+				word.Reset()
+				add(string(r))
+			case unicode.IsLetter(r) || unicode.IsNumber(r):
+				word.WriteRune(unicode.ToLower(r))
+			default:
+				add(word.String())
+				word.Reset()
+			}
+		}
+		add(word.String())
+	}
+	return terms
+}
+
+// isIdeograph 中日韩文字（词之间没有空格，逐字索引）
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// SentMessage 邮箱经发信中继发出的邮件（“已发送”文件夹）
+//
+// 发信配额按已发送记录统计，记录随邮箱一起删除。
+type SentMessage struct {
+	ID        string   `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	MailboxID string   `json:"mailboxId" gorm:"type:varchar(36);not null;index:idx_sent_messages_mailbox_sent,priority:1"`
+	UserID    string   `json:"-" gorm:"type:varchar(36);index:idx_sent_messages_user_sent,priority:1"` // 发信时邮箱所属用户（游客邮箱为空），用于按用户统计配额
+	From      string   `json:"from" gorm:"type:varchar(255)"`
+	To        []string `json:"to" gorm:"serializer:json;type:json"`
+	Cc        []string `json:"cc,omitempty" gorm:"serializer:json;type:json"`
+	Subject   string   `json:"subject" gorm:"type:varchar(500)"`
+	Text      string   `json:"text,omitempty" gorm:"type:text"`
+	HTML      string   `json:"html,omitempty" gorm:"type:text"`
+	// 邮件头中的 Message-ID（不含尖括号）
+	InternetMessageID string `json:"internetMessageId" gorm:"type:varchar(255)"`
+	// 回复的收件邮件 ID（不是回复时为空）
+	ReplyToMessageID string    `json:"replyToMessageId,omitempty" gorm:"type:varchar(36)"`
+	Size             int64     `json:"size" gorm:"default:0"` // 发出的原始邮件大小（字节）
+	SentAt           time.Time `json:"sentAt" gorm:"index:idx_sent_messages_mailbox_sent,priority:2;index:idx_sent_messages_user_sent,priority:2"`
+}
+
+// SentMessageFilter 统计已发送邮件的条件（MailboxID、UserID 至少一个非空）
+type SentMessageFilter struct {
+	MailboxID string
+	UserID    string
+	Since     time.Time // 只统计此时刻之后发出的邮件
+}
+
+// SentMessageUsage 已发送邮件的统计结果
+type SentMessageUsage struct {
+	Count    int64
+	OldestAt time.Time // 统计范围内最早一封的发送时间（Count 为 0 时为零值）
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SinkStatsRetention 黑洞模式小时桶的保留时长
+const SinkStatsRetention = 7 * 24 * time.Hour
+
+// SinkSettings 黑洞模式设置
+//
+// 开启后域名下任意收件人都被接收，邮件不入库、不通知，只累计汇总统计；
+// SampleRate > 0 时每 N 封抽取 1 封投递到诊断邮箱，便于排查发件方问题。
+type SinkSettings struct {
+	Enabled              bool   `json:"enabled" gorm:"default:false"`
+	SampleRate           int    `json:"sampleRate,omitempty" gorm:"default:0"`                  // 每 N 封抽样 1 封，0 表示不抽样
+	DiagnosticsMailboxID string `json:"diagnosticsMailboxId,omitempty" gorm:"type:varchar(36)"` // 抽样邮件投递的邮箱
+}
+
+// SinkHourlyCount 黑洞模式每小时的接收量
+type SinkHourlyCount struct {
+	Hour     time.Time `json:"hour"` // UTC 整点
+	Messages int64     `json:"messages"`
+	Bytes    int64     `json:"bytes"`
+}
+
+// SinkStats 黑洞模式汇总统计（不含任何可识别的发件人或收件人信息）
+type SinkStats struct {
+	Domain         string            `json:"domain"`
+	Messages       int64             `json:"messages"`      // 累计接收邮件数
+	Bytes          int64             `json:"bytes"`         // 累计接收字节数
+	UniqueSenders  int64             `json:"uniqueSenders"` // 去重发件人数（近似值）
+	Sampled        int64             `json:"sampled"`       // 抽样投递到诊断邮箱的邮件数
+	LastReceivedAt *time.Time        `json:"lastReceivedAt,omitempty"`
+	Hourly         []SinkHourlyCount `json:"hourly"` // 保留期内有数据的小时桶（升序）
+}
+
+// SinkStatsRepository 黑洞模式统计仓储接口
+type SinkStatsRepository interface {
+	// RecordSinkMessage 累计一封邮件，返回累计后的邮件数（用于抽样）
+	RecordSinkMessage(ctx context.Context, domainName, sender string, size int64, at time.Time) (int64, error)
+
+	// RecordSinkSample 累计一封抽样投递的邮件
+	RecordSinkSample(ctx context.Context, domainName string) error
+
+	// GetSinkStats 获取域名的汇总统计（没有数据时返回零值统计）
+	GetSinkStats(ctx context.Context, domainName string) (*SinkStats, error)
+}
@@ -0,0 +1,220 @@
+package domain
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 高亮哨兵字符（Unicode 私有区）
+//
+// SQL（ts_headline）和 Go 提取器都先用哨兵标记命中位置，再统一经 FinalizeSnippet
+// 转义 HTML 并替换为最终标记，保证两条路径输出格式一致。
+const (
+	SnippetStartSentinel = "\uE000"
+	SnippetStopSentinel  = "\uE001"
+)
+
+// SnippetWidth 摘要窗口长度（字符数）
+const SnippetWidth = 160
+
+const snippetEllipsis = "…"
+
+var sentinelStripper = strings.NewReplacer(SnippetStartSentinel, "", SnippetStopSentinel, "")
+
+// HighlightOptions 搜索高亮选项
+type HighlightOptions struct {
+	PreTag  string // 命中前标记（默认 <em>）
+	PostTag string // 命中后标记（默认 </em>）
+}
+
+// DefaultHighlightOptions 默认高亮选项
+func DefaultHighlightOptions() HighlightOptions {
+	return HighlightOptions{PreTag: "<em>", PostTag: "</em>"}
+}
+
+// ErrInvalidHighlightTag 自定义高亮标记不是白名单中的标签
+var ErrInvalidHighlightTag = errors.New("highlight markers must be <em>, <mark>, <b>, <strong> or <span> tags without attributes")
+
+// highlightTags 可用作高亮标记的标签（标记原样插入已转义的摘要，只接受不带属性的行内标签）
+var highlightTags = map[string]bool{"em": true, "mark": true, "b": true, "strong": true, "span": true}
+
+var (
+	highlightOpenPattern  = regexp.MustCompile(`^<([a-z]+)>$`)
+	highlightClosePattern = regexp.MustCompile(`^</([a-z]+)>$`)
+)
+
+// ParseHighlightOptions 校验客户端指定的高亮标记
+//
+// 只接受白名单标签（如 <mark> 与 </mark>），不区分大小写；只给出一侧时另一侧取同一标签的默认标记，
+// 两侧都为空时使用默认的 <em></em>。
+func ParseHighlightOptions(pre, post string) (HighlightOptions, error) {
+	pre, post = strings.ToLower(strings.TrimSpace(pre)), strings.ToLower(strings.TrimSpace(post))
+	if pre == "" && post == "" {
+		return DefaultHighlightOptions(), nil
+	}
+
+	var preName, postName string
+	if pre != "" {
+		m := highlightOpenPattern.FindStringSubmatch(pre)
+		if m == nil || !highlightTags[m[1]] {
+			return HighlightOptions{}, ErrInvalidHighlightTag
+		}
+		preName = m[1]
+	}
+	if post != "" {
+		m := highlightClosePattern.FindStringSubmatch(post)
+		if m == nil || !highlightTags[m[1]] {
+			return HighlightOptions{}, ErrInvalidHighlightTag
+		}
+		postName = m[1]
+	}
+	switch {
+	case preName == "":
+		preName = postName
+	case postName == "":
+		postName = preName
+	case preName != postName:
+		return HighlightOptions{}, ErrInvalidHighlightTag
+	}
+	return HighlightOptions{PreTag: "<" + preName + ">", PostTag: "</" + postName + ">"}, nil
+}
+
+// MessageSnippets 单封邮件各字段的命中摘要
+type MessageSnippets struct {
+	Subject string `json:"subject,omitempty"`
+	Text    string `json:"text,omitempty"`
+	From    string `json:"from,omitempty"`
+}
+
+// IsEmpty 是否没有任何摘要
+func (s MessageSnippets) IsEmpty() bool {
+	return s.Subject == "" && s.Text == "" && s.From == ""
+}
+
+// SearchTerms 将查询拆分为去重后的小写关键词
+func SearchTerms(query string) []string {
+	fields := strings.Fields(strings.ToLower(query))
+	terms := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if !seen[f] {
+			seen[f] = true
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// BuildSnippet 提取首个命中附近的摘要并高亮全部关键词
+//
+// 不区分大小写，按 rune 截取避免切断多字节字符，返回值已转义 HTML。
+// 未命中时返回空字符串。
+func BuildSnippet(text string, terms []string, opts HighlightOptions) string {
+	raw := markSnippet(text, terms, SnippetWidth)
+	if raw == "" {
+		return ""
+	}
+	return FinalizeSnippet(raw, opts)
+}
+
+// FinalizeSnippet 转义带哨兵标记的摘要并替换为最终高亮标记
+//
+// 高亮标记不转义、原样插入，来自客户端的标记须先经 ParseHighlightOptions 校验。
+func FinalizeSnippet(raw string, opts HighlightOptions) string {
+	if !strings.Contains(raw, SnippetStartSentinel) {
+		return ""
+	}
+	escaped := html.EscapeString(raw)
+	escaped = strings.ReplaceAll(escaped, SnippetStartSentinel, opts.PreTag)
+	return strings.ReplaceAll(escaped, SnippetStopSentinel, opts.PostTag)
+}
+
+// markSnippet 截取窗口并用哨兵包裹命中关键词
+func markSnippet(text string, terms []string, width int) string {
+	if text == "" || len(terms) == 0 {
+		return ""
+	}
+	// 原文中的哨兵字符会伪造高亮，先剔除
+	text = sentinelStripper.Replace(text)
+
+	runes := []rune(text)
+	// 逐 rune 转小写，保证下标与原文一一对应
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	termRunes := make([][]rune, 0, len(terms))
+	for _, t := range terms {
+		if t != "" {
+			termRunes = append(termRunes, []rune(t))
+		}
+	}
+
+	first, firstLen := -1, 0
+	for i := range lower {
+		if n := matchAt(lower, i, termRunes); n > 0 {
+			first, firstLen = i, n
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	// 让首个命中位于窗口中部
+	start := first - (width-firstLen)/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + width
+	if end > len(runes) {
+		end = len(runes)
+		if start = end - width; start < 0 {
+			start = 0
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(snippetEllipsis)
+	}
+	for i := start; i < end; {
+		if n := matchAt(lower, i, termRunes); n > 0 && i+n <= end {
+			b.WriteString(SnippetStartSentinel)
+			b.WriteString(string(runes[i : i+n]))
+			b.WriteString(SnippetStopSentinel)
+			i += n
+			continue
+		}
+		b.WriteRune(runes[i])
+		i++
+	}
+	if end < len(runes) {
+		b.WriteString(snippetEllipsis)
+	}
+	return b.String()
+}
+
+// matchAt 返回在位置 i 命中的最长关键词长度，未命中返回 0
+func matchAt(lower []rune, i int, terms [][]rune) int {
+	best := 0
+	for _, t := range terms {
+		if len(t) <= best || i+len(t) > len(lower) {
+			continue
+		}
+		ok := true
+		for j, r := range t {
+			if lower[i+j] != r {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			best = len(t)
+		}
+	}
+	return best
+}
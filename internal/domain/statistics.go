@@ -26,3 +26,46 @@ type ActivityLog struct {
 	IP        string    `json:"ip"`
 	CreatedAt time.Time `json:"createdAt"`
 }
+
+// MessageStatsQuery 邮件统计查询条件（时间均为 UTC，[Since, Until) 左闭右开）
+type MessageStatsQuery struct {
+	MailboxIDs []string
+	Since      time.Time
+	Until      time.Time
+	TopSenders int // 返回的发件人数量上限
+}
+
+// HourlyCount 每小时收件数
+type HourlyCount struct {
+	Hour  time.Time `json:"hour"` // UTC 整点
+	Count int       `json:"count"`
+}
+
+// SenderCount 发件人收件数
+type SenderCount struct {
+	Sender string `json:"sender"`
+	Count  int    `json:"count"`
+}
+
+// LocalPartCount 按邮箱前缀统计的收件数
+type LocalPartCount struct {
+	LocalPart string `json:"localPart"`
+	Count     int    `json:"count"`
+}
+
+// MessageStats 邮件统计结果
+//
+// 存储层返回有数据的小时桶和按邮箱的计数；服务层补齐空桶并换算为邮箱前缀。
+type MessageStats struct {
+	Since       time.Time        `json:"since"`
+	Until       time.Time        `json:"until"`
+	Hourly      []HourlyCount    `json:"hourly"`
+	Total       int              `json:"total"`  // 窗口内收到的邮件数
+	Unread      int              `json:"unread"` // 窗口内未读邮件数
+	TopSenders  []SenderCount    `json:"topSenders"`
+	AverageSize int64            `json:"averageSize"` // 平均邮件大小（字节）
+	LocalParts  []LocalPartCount `json:"localParts,omitempty"`
+	Sink        *SinkStats       `json:"sink,omitempty"` // 域名黑洞模式统计（开启过黑洞模式的域名）
+
+	ByMailbox map[string]int `json:"-"` // 按邮箱ID的收件数
+}
@@ -1,90 +1,143 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Store 聚合所有存储接口
 type Store interface {
 	// ========== Mailbox Repository ==========
-	SaveMailbox(mailbox *Mailbox) error
-	GetMailbox(id string) (*Mailbox, error)
-	GetMailboxByAddress(address string) (*Mailbox, error)
-	ListMailboxes() []Mailbox
-	ListMailboxesByUserID(userID string) []Mailbox
-	DeleteMailbox(id string) error
-	DeleteExpiredMailboxes() (int, error)
-	DeleteMailboxesByUserID(userID string) error
+	SaveMailbox(ctx context.Context, mailbox *Mailbox) error
+	GetMailbox(ctx context.Context, id string) (*Mailbox, error)
+	GetMailboxByAddress(ctx context.Context, address string) (*Mailbox, error)
+	GetMailboxesByAddresses(ctx context.Context, addresses []string) ([]Mailbox, error)
+	ListMailboxes(ctx context.Context) []Mailbox
+	ListMailboxesByUserID(ctx context.Context, userID string) []Mailbox
+	ListMailboxesByOrgID(ctx context.Context, orgID string) []Mailbox
+	ListMailboxSummariesByUserID(ctx context.Context, userID string) ([]MailboxSummary, error)
+	ListMailboxSummariesByOrgID(ctx context.Context, orgID string) ([]MailboxSummary, error)
+	DeleteMailbox(ctx context.Context, id string) error
+	DeleteExpiredMailboxes(ctx context.Context) (int, error)
+	DeleteMailboxesByUserID(ctx context.Context, userID string) error
+	ListExpiredMailboxes(ctx context.Context, now time.Time) ([]Mailbox, error)
+	TouchMailbox(ctx context.Context, mailboxID string, at time.Time) error
+	ListIdleMailboxes(ctx context.Context, before, now time.Time) ([]Mailbox, error)
+	MarkMailboxIdle(ctx context.Context, mailboxID string, at time.Time, shortenTo *time.Time) error
+	ListPublicMailboxes(ctx context.Context, now time.Time) ([]Mailbox, error)
 
 	// ========== Message Repository ==========
-	SaveMessage(message *Message) error
-	ListMessages(mailboxID string) ([]Message, error)
-	GetMessage(mailboxID, messageID string) (*Message, error)
-	MarkMessageRead(mailboxID, messageID string) error
-	SearchMessages(criteria MessageSearchCriteria) (*MessageSearchResult, error)
+	SaveMessage(ctx context.Context, message *Message) error
+	SaveMessages(ctx context.Context, messages []*Message) error
+	ListMessages(ctx context.Context, mailboxID string) ([]Message, error)
+	GetMessage(ctx context.Context, mailboxID, messageID string) (*Message, error)
+	MarkMessageRead(ctx context.Context, mailboxID, messageID string) error
+	SearchMessages(ctx context.Context, criteria MessageSearchCriteria) (*MessageSearchResult, error)
+	GetMessageStats(ctx context.Context, query MessageStatsQuery) (*MessageStats, error)
 
 	// ========== User Repository ==========
-	CreateUser(user *User) error
-	GetUserByID(id string) (*User, error)
-	GetUserByEmail(email string) (*User, error)
-	GetUserByUsername(username string) (*User, error)
-	UpdateUser(user *User) error
-	UpdateLastLogin(userID string) error
+	CreateUser(ctx context.Context, user *User) error
+	GetUserByID(ctx context.Context, id string) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	UpdateUser(ctx context.Context, user *User) error
+	UpdateLastLogin(ctx context.Context, userID string) error
 
 	// ========== Admin Repository ==========
-	ListUsers(page, pageSize int, search string, role *UserRole, tier *UserTier, isActive *bool) ([]User, int, error)
-	DeleteUser(userID string) error
-	GetSystemStatistics() (*SystemStatistics, error)
-	GetDomainStatistics(domain string) (mailboxCount, messageCount int, err error)
+	ListUsers(ctx context.Context, page, pageSize int, search string, role *UserRole, tier *UserTier, isActive *bool) ([]User, int, error)
+	DeleteUser(ctx context.Context, userID string) error
+	GetSystemStatistics(ctx context.Context) (*SystemStatistics, error)
+	GetDomainStatistics(ctx context.Context, domain string) (mailboxCount, messageCount int, err error)
 
 	// ========== User Domain Repository ==========
-	SaveUserDomain(domain *UserDomain) error
-	GetUserDomain(id string) (*UserDomain, error)
-	GetUserDomainByDomain(domain string) (*UserDomain, error)
-	ListUserDomainsByUserID(userID string) ([]*UserDomain, error)
-	ListAllUserDomains() ([]*UserDomain, error)
-	DeleteUserDomain(id string) error
-	IncrementMailboxCount(domain string) error
-	DecrementMailboxCount(domain string) error
+	SaveUserDomain(ctx context.Context, domain *UserDomain) error
+	GetUserDomain(ctx context.Context, id string) (*UserDomain, error)
+	GetUserDomainByDomain(ctx context.Context, domain string) (*UserDomain, error)
+	ListUserDomainsByUserID(ctx context.Context, userID string) ([]*UserDomain, error)
+	ListUserDomainsByOrgID(ctx context.Context, orgID string) ([]*UserDomain, error)
+	ListAllUserDomains(ctx context.Context) ([]*UserDomain, error)
+	DeleteUserDomain(ctx context.Context, id string) error
+	IncrementMailboxCount(ctx context.Context, domain string) error
+	DecrementMailboxCount(ctx context.Context, domain string) error
 
 	// ========== System Domain Repository ==========
-	SaveSystemDomain(domain *SystemDomain) error
-	GetSystemDomain(id string) (*SystemDomain, error)
-	GetSystemDomainByDomain(domain string) (*SystemDomain, error)
-	ListSystemDomains() ([]*SystemDomain, error)
-	ListActiveSystemDomains() ([]*SystemDomain, error)
-	DeleteSystemDomain(id string) error
-	IncrementSystemDomainMailboxCount(domain string) error
-	DecrementSystemDomainMailboxCount(domain string) error
-	DeleteUnverifiedSystemDomains(before time.Time) (int, error)
+	SaveSystemDomain(ctx context.Context, domain *SystemDomain) error
+	GetSystemDomain(ctx context.Context, id string) (*SystemDomain, error)
+	GetSystemDomainByDomain(ctx context.Context, domain string) (*SystemDomain, error)
+	ListSystemDomains(ctx context.Context) ([]*SystemDomain, error)
+	ListActiveSystemDomains(ctx context.Context) ([]*SystemDomain, error)
+	DeleteSystemDomain(ctx context.Context, id string) error
+	IncrementSystemDomainMailboxCount(ctx context.Context, domain string) error
+	DecrementSystemDomainMailboxCount(ctx context.Context, domain string) error
+	DeleteUnverifiedSystemDomains(ctx context.Context, before time.Time) (int, error)
 
 	// ========== API Key Repository ==========
-	SaveAPIKey(apiKey *APIKey) error
-	GetAPIKey(id string) (*APIKey, error)
-	GetAPIKeyByKey(key string) (*APIKey, error)
-	ListAPIKeysByUserID(userID string) ([]*APIKey, error)
-	DeleteAPIKey(id string) error
-	UpdateAPIKeyLastUsed(id string) error
-	GetUserByAPIKey(apiKey string) (*User, error)
+	SaveAPIKey(ctx context.Context, apiKey *APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*APIKey, error)
+	GetAPIKeyByKey(ctx context.Context, key string) (*APIKey, error)
+	ListAPIKeysByUserID(ctx context.Context, userID string) ([]*APIKey, error)
+	DeleteAPIKey(ctx context.Context, id string) error
+	UpdateAPIKeyLastUsed(ctx context.Context, id string) error
+	GetUserByAPIKey(ctx context.Context, apiKey string) (*User, error)
 
 	// ========== Webhook Repository ==========
-	CreateWebhook(webhook *Webhook) error
-	GetWebhook(id string) (*Webhook, error)
-	ListWebhooks(userID string) ([]Webhook, error)
-	UpdateWebhook(webhook *Webhook) error
-	DeleteWebhook(id string) error
-	RecordDelivery(delivery *WebhookDelivery) error
-	GetDeliveries(webhookID string, limit int) ([]WebhookDelivery, error)
-	GetPendingDeliveries(limit int) ([]WebhookDelivery, error)
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
+	GetWebhook(ctx context.Context, id string) (*Webhook, error)
+	ListWebhooks(ctx context.Context, userID string) ([]Webhook, error)
+	ListWebhooksByOrgID(ctx context.Context, orgID string) ([]Webhook, error)
+	ListWebhooksByMailbox(ctx context.Context, mailboxID string) ([]Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook *Webhook) error
+	DeleteWebhook(ctx context.Context, id string) error
+	RecordDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	GetDeliveries(ctx context.Context, webhookID string, limit int) ([]WebhookDelivery, error)
+	GetPendingDeliveries(ctx context.Context, limit int) ([]WebhookDelivery, error)
+	CancelPendingDeliveries(ctx context.Context, mailboxID string) (int, error)
+	GetDelivery(ctx context.Context, id string) (*WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	ListDeadLetters(ctx context.Context, webhookID string, limit int) ([]WebhookDelivery, error)
 
 	// ========== Tag Repository ==========
-	CreateTag(tag *Tag) error
-	GetTag(id string) (*Tag, error)
-	GetTagByName(userID, name string) (*Tag, error)
-	ListTags(userID string) ([]TagWithCount, error)
-	UpdateTag(tag *Tag) error
-	DeleteTag(id string) error
-	AddMessageTag(messageID, tagID string) error
-	RemoveMessageTag(messageID, tagID string) error
-	GetMessageTags(messageID string) ([]Tag, error)
-	ListMessagesByTag(tagID string) ([]Message, error)
-	DeleteMessageTags(messageID string) error
+	CreateTag(ctx context.Context, tag *Tag) error
+	GetTag(ctx context.Context, id string) (*Tag, error)
+	GetTagByName(ctx context.Context, userID, name string) (*Tag, error)
+	ListTags(ctx context.Context, userID string) ([]TagWithCount, error)
+	ListTagsByOrgID(ctx context.Context, orgID string) ([]TagWithCount, error)
+	UpdateTag(ctx context.Context, tag *Tag) error
+	DeleteTag(ctx context.Context, id string) error
+	AddMessageTag(ctx context.Context, messageID, tagID string) error
+	RemoveMessageTag(ctx context.Context, messageID, tagID string) error
+	GetMessageTags(ctx context.Context, messageID string) ([]Tag, error)
+	ListMessagesByTag(ctx context.Context, tagID string) ([]Message, error)
+	DeleteMessageTags(ctx context.Context, messageID string) error
+
+	// ========== Organization Repository ==========
+	CreateOrganization(ctx context.Context, org *Organization) error
+	GetOrganization(ctx context.Context, id string) (*Organization, error)
+	SaveOrgMember(ctx context.Context, member *OrgMember) error
+	GetOrgMember(ctx context.Context, orgID, userID string) (*OrgMember, error)
+	ListOrgMembers(ctx context.Context, orgID string) ([]*OrgMember, error)
+	ListOrgMembershipsByUserID(ctx context.Context, userID string) ([]*OrgMember, error)
+	DeleteOrgMember(ctx context.Context, orgID, userID string) error
+	SaveOrgInvite(ctx context.Context, invite *OrgInvite) error
+	GetOrgInvite(ctx context.Context, token string) (*OrgInvite, error)
+	DeleteOrgInvite(ctx context.Context, token string) error
+
+	// ========== Distribution List Repository ==========
+	SaveDistributionList(ctx context.Context, list *DistributionList) error
+	GetDistributionList(ctx context.Context, id string) (*DistributionList, error)
+	GetDistributionListByAddress(ctx context.Context, address string) (*DistributionList, error)
+	ListDistributionListsByUserID(ctx context.Context, userID string) ([]*DistributionList, error)
+	ListDistributionListsByOrgID(ctx context.Context, orgID string) ([]*DistributionList, error)
+	DeleteDistributionList(ctx context.Context, id string) error
+	SaveDistributionListDelivery(ctx context.Context, delivery *DistributionListDelivery) error
+	ListDistributionListDeliveries(ctx context.Context, listID string, limit int) ([]*DistributionListDelivery, error)
+
+	// ========== Redaction Repository ==========
+	SaveMessageRedaction(ctx context.Context, redaction *MessageRedaction) error
+	GetMessageRedaction(ctx context.Context, mailboxID, messageID string) (*MessageRedaction, error)
+
+	// ========== Sink Stats Repository ==========
+	RecordSinkMessage(ctx context.Context, domainName, sender string, size int64, at time.Time) (int64, error)
+	RecordSinkSample(ctx context.Context, domainName string) error
+	GetSinkStats(ctx context.Context, domainName string) (*SinkStats, error)
 }
@@ -9,10 +9,19 @@ type SystemConfig struct {
 	Mailbox   MailboxConfig   `json:"mailbox"`
 	RateLimit RateLimitConfig `json:"rateLimit"`
 	Security  SecurityConfig  `json:"security"`
+	Maintenance MaintenanceConfig `json:"maintenance"`
+	JWTKeys   *JWTKeyRing     `json:"jwtKeys,omitempty"` // 轮换后的签名密钥（为空时使用启动配置）
+	Idle      IdlePolicyConfig `json:"idle"`
+	GuestWebhooks GuestWebhookConfig `json:"guestWebhooks"` // 邮箱 Webhook（凭邮箱令牌创建）的限制
+	Retention RetentionPolicyConfig `json:"retention"` // 按用户等级的邮件保留时长
+	Inbound   InboundProtectionConfig `json:"inbound"`   // SMTP 收信限流和灰名单
 	UpdatedAt time.Time       `json:"updatedAt"`
 	UpdatedBy string          `json:"updatedBy"` // 更新者用户ID
 }
 
+// MaxSMTPMessageSize 系统配置允许设置的单封邮件大小上限，也是 SMTP 协议层的兜底上限
+const MaxSMTPMessageSize = 100 << 20
+
 // SMTPConfig SMTP服务配置
 type SMTPConfig struct {
 	BindAddr   string `json:"bindAddr"`   // 监听地址，如 ":25"
@@ -28,6 +37,8 @@ type MailboxConfig struct {
 	AllowedDomains     []string `json:"allowedDomains"`     // 允许的域名列表
 	RequireVerification bool     `json:"requireVerification"` // 是否需要邮箱验证
 	MaxAliases         int      `json:"maxAliases"`         // 每个邮箱最大别名数
+	// 收信时保存的头名单；为 null 时使用启动配置（mailbox.captured_headers），空数组表示不保存
+	CapturedHeaders []string `json:"capturedHeaders"`
 }
 
 // RateLimitConfig 限流配置
@@ -45,6 +56,177 @@ type SecurityConfig struct {
 	PasswordMinLength int    `json:"passwordMinLength"` // 最小密码长度
 	EnableCaptcha    bool   `json:"enableCaptcha"`    // 是否启用验证码
 	MaxLoginAttempts int    `json:"maxLoginAttempts"` // 最大登录尝试次数
+	RequireAdminTwoFactor bool `json:"requireAdminTwoFactor"` // 管理员必须启用两步验证才能访问管理接口
+}
+
+// MaintenanceConfig 维护模式配置
+//
+// ReadOnly 为 true 时 API 拒绝所有写操作（返回 503），SMTP 在 MAIL FROM 阶段返回 421，
+// 让发件方稍后重试而不是退信。
+type MaintenanceConfig struct {
+	ReadOnly  bool       `json:"readOnly"`            // 是否处于只读维护模式
+	Message   string     `json:"message,omitempty"`   // 展示给客户端的维护说明
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"` // 最近一次切换时间
+	UpdatedBy string     `json:"updatedBy,omitempty"` // 最近一次切换的操作者用户ID
+}
+
+// 闲置邮箱处理方式
+const (
+	IdleActionNone       = "none"        // 不处理
+	IdleActionWarn       = "warn"        // 仅提醒所有者
+	IdleActionShortenTTL = "shorten-ttl" // 提醒并把有效期缩短到 WarnBefore 之后
+)
+
+// IdlePolicyConfig 闲置邮箱策略
+//
+// 用户邮箱超过 IdleAfter 未被访问（API 读取、WebSocket 订阅）即视为闲置，
+// 游客邮箱不参与。再次访问后闲置标记清除，被缩短的有效期恢复原值。
+type IdlePolicyConfig struct {
+	IdleAfter  string `json:"idleAfter"`  // 闲置判定时长，如 "720h"
+	WarnBefore string `json:"warnBefore"` // 提醒后保留的时长（shorten-ttl 时的新有效期），如 "72h"
+	Action     string `json:"action"`     // none / warn / shorten-ttl
+}
+
+// Enabled 是否启用闲置检测
+func (c IdlePolicyConfig) Enabled() bool {
+	return c.Action == IdleActionWarn || c.Action == IdleActionShortenTTL
+}
+
+// GuestWebhookConfig 邮箱 Webhook 限制
+//
+// 邮箱 Webhook 由只持有邮箱令牌的访客创建，投递失败后按更短的重试计划重试，
+// 避免无人维护的回调地址长期占用投递队列。
+type GuestWebhookConfig struct {
+	MaxPerMailbox int      `json:"maxPerMailbox"` // 每个邮箱最多创建的 Webhook 数
+	RetrySchedule []string `json:"retrySchedule"` // 失败后的重试间隔，如 ["1m", "5m"]，为空表示不重试
+}
+
+// DefaultGuestWebhookConfig 默认邮箱 Webhook 限制
+func DefaultGuestWebhookConfig() GuestWebhookConfig {
+	return GuestWebhookConfig{
+		MaxPerMailbox: 3,
+		RetrySchedule: []string{"1m", "5m", "15m"},
+	}
+}
+
+// WithDefaults 未设置的字段使用默认值（旧版本保存的系统配置没有该节）
+func (c GuestWebhookConfig) WithDefaults() GuestWebhookConfig {
+	defaults := DefaultGuestWebhookConfig()
+	if c.MaxPerMailbox <= 0 {
+		c.MaxPerMailbox = defaults.MaxPerMailbox
+	}
+	if c.RetrySchedule == nil {
+		c.RetrySchedule = defaults.RetrySchedule
+	}
+	return c
+}
+
+// RetryIntervals 解析重试间隔，忽略格式无效的项
+func (c GuestWebhookConfig) RetryIntervals() []time.Duration {
+	intervals := make([]time.Duration, 0, len(c.RetrySchedule))
+	for _, item := range c.RetrySchedule {
+		if d, err := time.ParseDuration(item); err == nil && d > 0 {
+			intervals = append(intervals, d)
+		}
+	}
+	return intervals
+}
+
+// RetentionPolicyConfig 按用户等级的邮件保留时长
+//
+// 邮件收到后超过所属邮箱用户等级的保留时长即被后台任务删除（邮箱本身不受影响）；
+// 时长为空表示不限制，邮件随邮箱过期。Guest 适用于没有关联用户的游客邮箱。
+type RetentionPolicyConfig struct {
+	Guest string              `json:"guest,omitempty"` // 游客邮箱，如 "1h"
+	Tiers map[UserTier]string `json:"tiers,omitempty"` // 如 {"free": "1h", "pro": "168h"}
+}
+
+// For 返回指定等级的保留时长（tier 为 nil 表示游客邮箱），0 表示不限制
+func (c RetentionPolicyConfig) For(tier *UserTier) time.Duration {
+	value := c.Guest
+	if tier != nil {
+		value = c.Tiers[*tier]
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// Enabled 是否有任何等级设置了保留时长
+func (c RetentionPolicyConfig) Enabled() bool {
+	if c.For(nil) > 0 {
+		return true
+	}
+	for tier := range c.Tiers {
+		if c.For(&tier) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// InboundProtectionConfig SMTP 收信保护
+//
+// 限制单个 IP 的并发连接数和单个发件域名的收信速率，并可对首次出现的
+// （发件 IP 网段、发件地址、收件地址）三元组返回临时错误（灰名单），正规 MTA 会稍后重试。
+// 计数保存在各实例内存中，多实例部署时每个实例分别计算。
+type InboundProtectionConfig struct {
+	MaxConnectionsPerIP   int               `json:"maxConnectionsPerIp"`   // 单 IP 最大并发连接数，0 表示不限制
+	SenderDomainPerMinute int               `json:"senderDomainPerMinute"` // 单个发件域名每分钟最多开始的投递数，0 表示不限制
+	Greylisting           GreylistingConfig `json:"greylisting"`
+}
+
+// GreylistingConfig 灰名单配置
+type GreylistingConfig struct {
+	Enabled bool   `json:"enabled"`
+	Delay   string `json:"delay,omitempty"`  // 首次出现后需等待的时长，默认 "5m"
+	Expiry  string `json:"expiry,omitempty"` // 三元组记录的保留时长（通过后再次收信无需等待），默认 "864h"
+}
+
+// DefaultGreylistDelay 和 DefaultGreylistExpiry 灰名单默认等待和保留时长
+const (
+	DefaultGreylistDelay  = 5 * time.Minute
+	DefaultGreylistExpiry = 36 * 24 * time.Hour
+)
+
+// DelayDuration 首次出现后需等待的时长（未设置或无效时使用默认值）
+func (c GreylistingConfig) DelayDuration() time.Duration {
+	if d, err := time.ParseDuration(c.Delay); err == nil && d > 0 {
+		return d
+	}
+	return DefaultGreylistDelay
+}
+
+// ExpiryDuration 三元组记录的保留时长（未设置或无效时使用默认值）
+func (c GreylistingConfig) ExpiryDuration() time.Duration {
+	if d, err := time.ParseDuration(c.Expiry); err == nil && d > 0 {
+		return d
+	}
+	return DefaultGreylistExpiry
+}
+
+// JWTKeyRing JWT 签名密钥（管理员轮换后写入，各实例通过运行时配置同步）
+type JWTKeyRing struct {
+	Current   JWTKey    `json:"current"`
+	Previous  *JWTKey   `json:"previous,omitempty"`
+	RotatedAt time.Time `json:"rotatedAt"`
+	RotatedBy string    `json:"rotatedBy,omitempty"` // 操作者用户ID
+}
+
+// JWTKey 签名密钥
+type JWTKey struct {
+	ID       string     `json:"id"`
+	Secret   string     `json:"secret"`
+	RetireAt *time.Time `json:"retireAt,omitempty"` // 旧密钥停止验证的时间
+}
+
+// WithoutSecrets 返回去掉签名密钥的副本（用于 API 响应）
+func (c *SystemConfig) WithoutSecrets() *SystemConfig {
+	copied := *c
+	copied.JWTKeys = nil
+	return &copied
 }
 
 // DefaultSystemConfig 返回默认系统配置
@@ -77,6 +259,12 @@ func DefaultSystemConfig() *SystemConfig {
 			EnableCaptcha:     false,
 			MaxLoginAttempts:  5,
 		},
+		Idle: IdlePolicyConfig{
+			IdleAfter:  "720h",
+			WarnBefore: "72h",
+			Action:     IdleActionNone,
+		},
+		GuestWebhooks: DefaultGuestWebhookConfig(),
 		UpdatedAt: time.Now(),
 	}
 }
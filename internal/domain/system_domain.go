@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // SystemDomainStatus 系统域名状态
 type SystemDomainStatus string
@@ -30,34 +33,37 @@ type SystemDomain struct {
 	MXRecords    []string             `json:"mxRecords" gorm:"serializer:json;type:json"`
 	MailboxCount int                  `json:"mailboxCount" gorm:"default:0"`
 	Notes        string               `json:"notes" gorm:"type:text"`
+	Sink         SinkSettings         `json:"sink" gorm:"embedded;embeddedPrefix:sink_"` // 黑洞模式
+	// 是否允许在创建邮箱时直接设为公开收件箱（其余情况只能由管理员设置）
+	AllowPublicInboxes bool `json:"allowPublicInboxes" gorm:"default:false"`
 }
 
 // SystemDomainRepository 系统域名仓储接口
 type SystemDomainRepository interface {
 	// SaveSystemDomain 保存系统域名
-	SaveSystemDomain(domain *SystemDomain) error
+	SaveSystemDomain(ctx context.Context, domain *SystemDomain) error
 
 	// GetSystemDomain 根据 ID 获取系统域名
-	GetSystemDomain(id string) (*SystemDomain, error)
+	GetSystemDomain(ctx context.Context, id string) (*SystemDomain, error)
 
 	// GetSystemDomainByDomain 根据域名获取
-	GetSystemDomainByDomain(domain string) (*SystemDomain, error)
+	GetSystemDomainByDomain(ctx context.Context, domain string) (*SystemDomain, error)
 
 	// ListSystemDomains 获取所有系统域名
-	ListSystemDomains() ([]*SystemDomain, error)
+	ListSystemDomains(ctx context.Context) ([]*SystemDomain, error)
 
 	// ListActiveSystemDomains 获取所有已激活的系统域名
-	ListActiveSystemDomains() ([]*SystemDomain, error)
+	ListActiveSystemDomains(ctx context.Context) ([]*SystemDomain, error)
 
 	// DeleteSystemDomain 删除系统域名
-	DeleteSystemDomain(id string) error
+	DeleteSystemDomain(ctx context.Context, id string) error
 
 	// IncrementSystemDomainMailboxCount 增加系统域名邮箱计数
-	IncrementSystemDomainMailboxCount(domain string) error
+	IncrementSystemDomainMailboxCount(ctx context.Context, domain string) error
 
 	// DecrementSystemDomainMailboxCount 减少系统域名邮箱计数
-	DecrementSystemDomainMailboxCount(domain string) error
+	DecrementSystemDomainMailboxCount(ctx context.Context, domain string) error
 
 	// DeleteUnverifiedSystemDomains 删除指定时间前创建且未验证的域名
-	DeleteUnverifiedSystemDomains(before time.Time) (int, error)
+	DeleteUnverifiedSystemDomains(ctx context.Context, before time.Time) (int, error)
 }
@@ -12,6 +12,9 @@ const (
 	TierEnterprise UserTier = "enterprise"
 )
 
+// UserTiers 全部用户等级（从低到高）
+var UserTiers = []UserTier{TierFree, TierBasic, TierPro, TierEnterprise}
+
 // UserRole 用户角色
 type UserRole string
 
@@ -34,6 +37,18 @@ type User struct {
 	CreatedAt       time.Time  `json:"createdAt"`
 	UpdatedAt       time.Time  `json:"updatedAt"`
 	LastLoginAt     *time.Time `json:"lastLoginAt,omitempty"`
+	LockedUntil     *time.Time `json:"lockedUntil,omitempty"`              // 连续登录失败后锁定至该时间
+	RecentLoginIPs  []string   `json:"-" gorm:"serializer:json;type:json"` // 最近成功登录的 IP（新 IP 登录时提醒）
+	// 用户自定义垃圾邮件阈值（为空时使用系统配置，邮箱自定义阈值优先）
+	SpamQuarantineScore *float64 `json:"spamQuarantineScore,omitempty"`
+	SpamRejectScore     *float64 `json:"spamRejectScore,omitempty"`
+	// 两步验证（TOTP）：密钥加密保存，设置后验证通过才启用；恢复码只保存 SHA-256 摘要
+	TOTPSecret    string   `json:"-" gorm:"column:totp_secret;type:varchar(255)"`
+	TOTPEnabled   bool     `json:"twoFactorEnabled" gorm:"column:totp_enabled;default:false"`
+	TOTPLastStep  int64    `json:"-" gorm:"column:totp_last_step;default:0"` // 最近一次使用的验证码时间步（防重放）
+	RecoveryCodes []string `json:"-" gorm:"column:recovery_codes;serializer:json;type:json"`
+	// 应用专用密码代数：参与派生应用密码，重置应用密码、修改密码、注销全部会话时递增，旧应用密码随之失效
+	AppPasswordGeneration int `json:"-" gorm:"default:0"`
 }
 
 // IsAdmin 判断用户是否为管理员
@@ -53,6 +68,15 @@ type Quota struct {
 	MaxMessagesPerMailbox   int    `json:"maxMessagesPerMailbox"`
 	MaxAPIRequestsPerMinute int    `json:"maxApiRequestsPerMinute"`
 	MaxConcurrentRequests   int    `json:"maxConcurrentRequests"`
+	MaxMailboxLifetimeHours int    `json:"maxMailboxLifetimeHours"` // 邮箱从创建起最长生存时间（续期上限），-1 表示无限制
+}
+
+// MaxMailboxLifetime 邮箱最长生存时间，0 表示无限制
+func (q Quota) MaxMailboxLifetime() time.Duration {
+	if q.MaxMailboxLifetimeHours <= 0 {
+		return 0
+	}
+	return time.Duration(q.MaxMailboxLifetimeHours) * time.Hour
 }
 
 // DefaultQuotas 返回不同等级的默认配额
@@ -64,6 +88,7 @@ func DefaultQuotas(tier UserTier) Quota {
 			MaxMessagesPerMailbox:   100,
 			MaxAPIRequestsPerMinute: 100,
 			MaxConcurrentRequests:   20,
+			MaxMailboxLifetimeHours: 168,
 		}
 	case TierPro:
 		return Quota{
@@ -71,6 +96,7 @@ func DefaultQuotas(tier UserTier) Quota {
 			MaxMessagesPerMailbox:   500,
 			MaxAPIRequestsPerMinute: 500,
 			MaxConcurrentRequests:   50,
+			MaxMailboxLifetimeHours: 720,
 		}
 	case TierEnterprise:
 		return Quota{
@@ -78,6 +104,7 @@ func DefaultQuotas(tier UserTier) Quota {
 			MaxMessagesPerMailbox:   -1,
 			MaxAPIRequestsPerMinute: -1,
 			MaxConcurrentRequests:   100,
+			MaxMailboxLifetimeHours: -1,
 		}
 	default: // TierFree
 		return Quota{
@@ -85,6 +112,7 @@ func DefaultQuotas(tier UserTier) Quota {
 			MaxMessagesPerMailbox:   30,
 			MaxAPIRequestsPerMinute: 30,
 			MaxConcurrentRequests:   5,
+			MaxMailboxLifetimeHours: 24,
 		}
 	}
 }
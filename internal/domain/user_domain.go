@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // DomainMode 域名模式
 type DomainMode string
@@ -10,10 +13,21 @@ const (
 	DomainModeShared DomainMode = "shared"
 	// DomainModeExclusive 独享模式（付费）- 只有所有者可以创建该域名下的邮箱
 	DomainModeExclusive DomainMode = "exclusive"
-	// DomainModeCatchAll 通配模式 - 捕获所有发往该域名的邮件
+	// DomainModeCatchAll 通配模式 - 只有所有者可以创建邮箱，发往不存在地址的邮件由通配设置接收
 	DomainModeCatchAll DomainMode = "catch_all"
+	// DomainModeWhitelist 白名单模式（免费）- 任何人都可以创建邮箱，但前缀必须在域名白名单中，发往其他前缀的邮件拒收
+	DomainModeWhitelist DomainMode = "whitelist"
 )
 
+// CatchAllSettings 通配收件设置（仅通配模式的已验证域名生效）
+//
+// 收件人在域名下没有邮箱、别名或分发列表时：MailboxID 非空则投递到该邮箱，
+// 为空则以收件地址为域名所有者自动创建邮箱。
+type CatchAllSettings struct {
+	Enabled   bool   `json:"enabled" gorm:"default:false"`
+	MailboxID string `json:"mailboxId,omitempty" gorm:"type:varchar(36)"` // 通配邮箱，为空时自动创建
+}
+
 // DomainStatus 域名状态
 type DomainStatus string
 
@@ -30,48 +44,52 @@ const (
 
 // UserDomain 用户自定义域名
 type UserDomain struct {
-	ID           string       `json:"id" gorm:"primaryKey;type:varchar(36)"`
-	UserID       string       `json:"userId" gorm:"type:varchar(36);index;not null"`
-	Domain       string       `json:"domain" gorm:"uniqueIndex;type:varchar(100);not null"`
-	Mode         DomainMode   `json:"mode" gorm:"type:varchar(20);default:'shared'"`
-	Status       DomainStatus `json:"status" gorm:"type:varchar(20);default:'pending';index"`
-	VerifyToken  string       `json:"verifyToken" gorm:"type:varchar(255)"`
-	VerifyMethod string       `json:"verifyMethod" gorm:"type:varchar(20);default:'dns_txt'"`
-	VerifiedAt   *time.Time   `json:"verifiedAt"`
-	LastCheckAt  *time.Time   `json:"lastCheckAt"`
-	CreatedAt    time.Time    `json:"createdAt"`
-	UpdatedAt    time.Time    `json:"updatedAt" gorm:"autoUpdateTime"`
-	ExpiresAt    *time.Time   `json:"expiresAt"`
-	MXRecords    []string     `json:"mxRecords" gorm:"serializer:json;type:json"`
-	IsActive     bool         `json:"isActive" gorm:"default:false;index"`
-	MailboxCount int          `json:"mailboxCount" gorm:"default:0"`
-	MonthlyFee   float64      `json:"monthlyFee" gorm:"type:decimal(10,2);default:0.00"`
-	Notes        string       `json:"notes,omitempty" gorm:"type:text"`
+	ID           string           `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID       string           `json:"userId" gorm:"type:varchar(36);index;not null"`
+	OrgID        *string          `json:"orgId,omitempty" gorm:"type:varchar(36);index"` // 所属组织（可选）
+	Domain       string           `json:"domain" gorm:"uniqueIndex;type:varchar(100);not null"`
+	Mode         DomainMode       `json:"mode" gorm:"type:varchar(20);default:'shared'"`
+	Status       DomainStatus     `json:"status" gorm:"type:varchar(20);default:'pending';index"`
+	VerifyToken  string           `json:"verifyToken" gorm:"type:varchar(255)"`
+	VerifyMethod string           `json:"verifyMethod" gorm:"type:varchar(20);default:'dns_txt'"`
+	VerifiedAt   *time.Time       `json:"verifiedAt"`
+	LastCheckAt  *time.Time       `json:"lastCheckAt"`
+	CreatedAt    time.Time        `json:"createdAt"`
+	UpdatedAt    time.Time        `json:"updatedAt" gorm:"autoUpdateTime"`
+	ExpiresAt    *time.Time       `json:"expiresAt"`
+	GraceNotices int              `json:"graceNotices,omitempty" gorm:"default:0"` // 过期宽限期内已发送的通知次数（续期后清零）
+	MXRecords    []string         `json:"mxRecords" gorm:"serializer:json;type:json"`
+	IsActive     bool             `json:"isActive" gorm:"default:false;index"`
+	MailboxCount int              `json:"mailboxCount" gorm:"default:0"`
+	MonthlyFee   float64          `json:"monthlyFee" gorm:"type:decimal(10,2);default:0.00"`
+	Notes        string           `json:"notes,omitempty" gorm:"type:text"`
+	Sink         SinkSettings     `json:"sink" gorm:"embedded;embeddedPrefix:sink_"`          // 黑洞模式（仅独享模式的已验证域名）
+	CatchAll     CatchAllSettings `json:"catchAll" gorm:"embedded;embeddedPrefix:catch_all_"` // 通配收件（仅通配模式的已验证域名）
 }
 
 // UserDomainRepository 用户域名仓储接口
 type UserDomainRepository interface {
 	// SaveUserDomain 保存用户域名
-	SaveUserDomain(domain *UserDomain) error
+	SaveUserDomain(ctx context.Context, domain *UserDomain) error
 
 	// GetUserDomain 根据 ID 获取用户域名
-	GetUserDomain(id string) (*UserDomain, error)
+	GetUserDomain(ctx context.Context, id string) (*UserDomain, error)
 
 	// GetUserDomainByDomain 根据域名获取
-	GetUserDomainByDomain(domain string) (*UserDomain, error)
+	GetUserDomainByDomain(ctx context.Context, domain string) (*UserDomain, error)
 
 	// ListUserDomainsByUserID 获取用户的所有域名
-	ListUserDomainsByUserID(userID string) ([]*UserDomain, error)
+	ListUserDomainsByUserID(ctx context.Context, userID string) ([]*UserDomain, error)
 
 	// ListAllUserDomains 获取所有用户域名
-	ListAllUserDomains() ([]*UserDomain, error)
+	ListAllUserDomains(ctx context.Context) ([]*UserDomain, error)
 
 	// DeleteUserDomain 删除用户域名
-	DeleteUserDomain(id string) error
+	DeleteUserDomain(ctx context.Context, id string) error
 
 	// IncrementMailboxCount 增加邮箱计数
-	IncrementMailboxCount(domain string) error
+	IncrementMailboxCount(ctx context.Context, domain string) error
 
 	// DecrementMailboxCount 减少邮箱计数
-	DecrementMailboxCount(domain string) error
+	DecrementMailboxCount(ctx context.Context, domain string) error
 }
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// UserSession 用户登录会话
+//
+// 每次登录创建一个会话，访问令牌和刷新令牌都带会话ID（sid）。刷新时轮换两个令牌并记录新的 jti，
+// 出示已被轮换掉的刷新令牌视为令牌泄露，整个会话作废。
+type UserSession struct {
+	ID              string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID          string    `json:"userId" gorm:"type:varchar(36);not null;index"`
+	RefreshTokenID  string    `json:"refreshTokenId" gorm:"type:varchar(36);not null"` // 当前有效的刷新令牌 jti
+	AccessTokenID   string    `json:"accessTokenId" gorm:"type:varchar(36)"`           // 最近签发的访问令牌 jti（撤销会话时加入黑名单）
+	AccessExpiresAt time.Time `json:"accessExpiresAt"`
+	IP              string    `json:"ip" gorm:"type:varchar(45)"`
+	UserAgent       string    `json:"userAgent" gorm:"type:varchar(512)"`
+	CreatedAt       time.Time `json:"createdAt"`
+	LastUsedAt      time.Time `json:"lastUsedAt"`             // 登录或最近一次刷新的时间
+	ExpiresAt       time.Time `json:"expiresAt" gorm:"index"` // 刷新令牌过期时间
+}
@@ -0,0 +1,13 @@
+package domain
+
+// Verification 入库时从邮件正文中提取的一次性验证码和验证链接
+type Verification struct {
+	Code  string   `json:"code,omitempty"`  // 最可能的验证码
+	Codes []string `json:"codes,omitempty"` // 全部候选验证码，按可信度排序（第一个即 Code）
+	Links []string `json:"links,omitempty"` // 验证、激活、登录或重置密码链接
+}
+
+// Empty 是否既没有验证码也没有验证链接
+func (v *Verification) Empty() bool {
+	return v == nil || (v.Code == "" && len(v.Links) == 0)
+}
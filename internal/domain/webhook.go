@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // WebhookEventType Webhook 事件类型
 type WebhookEventType string
@@ -14,15 +17,45 @@ const (
 	WebhookEventTagUpdated     WebhookEventType = "tag.updated"     // 标签更新
 	WebhookEventTagDeleted     WebhookEventType = "tag.deleted"     // 标签删除
 	WebhookEventMessageTagged  WebhookEventType = "message.tagged"  // 邮件添加标签
+	WebhookEventDomainClaimed  WebhookEventType = "domain.claimed"  // 用户域名被转为系统域名
+	WebhookEventDomainExpiring WebhookEventType = "domain.expiring" // 用户域名已过期，处于宽限期
+	WebhookEventMailboxIdle    WebhookEventType = "mailbox.idle"    // 邮箱长期未被访问
+	WebhookEventMailboxExpired WebhookEventType = "mailbox.expired" // 邮箱已过期（删除前发出）
+	WebhookEventMessageDeleted WebhookEventType = "message.deleted" // 邮件被删除或邮箱被清空
+	WebhookEventDomainVerified WebhookEventType = "domain.verified" // 用户域名通过验证
+	WebhookEventQuotaExceeded  WebhookEventType = "quota.exceeded"  // 邮箱、发信或组织配额用尽
+)
+
+// ValidWebhookEvent 是否为支持的事件类型
+func ValidWebhookEvent(event string) bool {
+	switch WebhookEventType(event) {
+	case WebhookEventMailReceived, WebhookEventMailRead, WebhookEventMailboxCreated, WebhookEventMailboxDeleted,
+		WebhookEventTagCreated, WebhookEventTagUpdated, WebhookEventTagDeleted, WebhookEventMessageTagged,
+		WebhookEventDomainClaimed, WebhookEventDomainExpiring, WebhookEventMailboxIdle, WebhookEventMailboxExpired,
+		WebhookEventMessageDeleted, WebhookEventDomainVerified, WebhookEventQuotaExceeded:
+		return true
+	}
+	return false
+}
+
+// Webhook 归属类型
+const (
+	WebhookOwnerUser    = "user"    // 用户（或组织）创建，需要登录
+	WebhookOwnerMailbox = "mailbox" // 凭邮箱令牌创建，只接收该邮箱的事件，随邮箱删除
 )
 
 // Webhook Webhook 配置
 type Webhook struct {
 	ID          string           `json:"id" gorm:"primaryKey;type:varchar(36)"`
 	UserID      string           `json:"userId" gorm:"type:varchar(36);index;not null"`
+	OrgID       *string          `json:"orgId,omitempty" gorm:"type:varchar(36);index"` // 所属组织（可选）
+	OwnerType   string           `json:"ownerType" gorm:"type:varchar(16);default:'user';index:idx_webhooks_owner,priority:1"` // 归属类型（user / mailbox）
+	OwnerID     string           `json:"ownerId,omitempty" gorm:"type:varchar(36);index:idx_webhooks_owner,priority:2"`     // 邮箱 Webhook 所属的邮箱ID（此时 UserID 为空）
 	URL         string           `json:"url" gorm:"type:varchar(500);not null"`
 	Events      []string         `json:"events" gorm:"serializer:json;type:json"`
-	Secret      string           `json:"secret" gorm:"type:varchar(255)"`
+	Secret      string           `json:"secret" gorm:"type:varchar(255)"` // 签名密钥（X-Tempmail-Signature）
+	PayloadFormat   string `json:"payloadFormat" gorm:"type:varchar(16);default:'headers'"` // 回调内容格式（headers / full / custom）
+	PayloadTemplate string `json:"payloadTemplate,omitempty" gorm:"type:text"`             // custom 格式的 Go 模板，输出 JSON
 	IsActive    bool             `json:"isActive" gorm:"default:true"`
 	RetryCount  int              `json:"retryCount" gorm:"default:0"`
 	LastError   string           `json:"lastError" gorm:"type:text"`
@@ -31,18 +64,32 @@ type Webhook struct {
 	UpdatedAt   time.Time        `json:"updatedAt"`
 }
 
+// Webhook 回调内容格式
+const (
+	WebhookPayloadHeaders = "headers" // 事件元数据和邮件头字段（默认）
+	WebhookPayloadFull    = "full"    // 另含邮件正文、附件列表和提取的验证码
+	WebhookPayloadCustom  = "custom"  // 按 Go 模板生成的自定义 JSON
+)
+
+// MailboxScoped 是否为邮箱 Webhook
+func (w *Webhook) MailboxScoped() bool {
+	return w.OwnerType == WebhookOwnerMailbox
+}
+
 // WebhookEvent Webhook 事件数据
 type WebhookEvent struct {
 	ID        string           `json:"id"`
 	Event     WebhookEventType `json:"event"`     // 事件类型
 	Timestamp time.Time        `json:"timestamp"` // 事件时间
 	Data      interface{}      `json:"data"`      // 事件数据
+	Test      bool             `json:"test,omitempty"` // 测试投递（/v1/webhooks/:id/test）
 }
 
 // WebhookDelivery Webhook 投递记录
 type WebhookDelivery struct {
 	ID          string           `json:"id"`
 	WebhookID   string           `json:"webhookId"`
+	MailboxID   string           `json:"mailboxId,omitempty" gorm:"type:varchar(36);index"` // 关联邮箱（邮件类事件）
 	Event       WebhookEventType `json:"event"`
 	Payload     string           `json:"payload"`      // JSON payload
 	StatusCode  int              `json:"statusCode"`   // HTTP 状态码
@@ -50,34 +97,56 @@ type WebhookDelivery struct {
 	Duration    int64            `json:"duration"`     // 请求耗时（毫秒）
 	Success     bool             `json:"success"`      // 是否成功
 	Error       string           `json:"error"`        // 错误信息
+	ErrorKind   string           `json:"errorKind,omitempty" gorm:"type:varchar(32)"` // 失败类型（network / http_status / circuit_open）
 	Attempts    int              `json:"attempts"`     // 尝试次数
 	NextRetry   *time.Time       `json:"nextRetry"`    // 下次重试时间
+	DeadLetter    bool       `json:"deadLetter,omitempty" gorm:"default:false;index"` // 重试用尽，等待人工重新投递
+	RedeliveredAt *time.Time `json:"redeliveredAt,omitempty"`                         // 人工重新投递的时间（之后不再列入死信）
 	CreatedAt   time.Time        `json:"createdAt"`
 }
 
+// Webhook 投递失败类型
+const (
+	WebhookErrorKindNetwork     = "network"      // 连接或请求失败
+	WebhookErrorKindHTTPStatus  = "http_status"  // 接收方返回非 2xx
+	WebhookErrorKindCircuitOpen = "circuit_open" // 目标主机熔断中，未发起连接
+)
+
 // WebhookRepository Webhook 仓储接口
 type WebhookRepository interface {
 	// CreateWebhook 创建 Webhook
-	CreateWebhook(webhook *Webhook) error
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
 	
 	// GetWebhook 获取 Webhook
-	GetWebhook(id string) (*Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*Webhook, error)
 	
 	// ListWebhooks 列出用户的 Webhooks
-	ListWebhooks(userID string) ([]Webhook, error)
+	ListWebhooks(ctx context.Context, userID string) ([]Webhook, error)
+	
+	// ListWebhooksByMailbox 列出邮箱 Webhooks
+	ListWebhooksByMailbox(ctx context.Context, mailboxID string) ([]Webhook, error)
 	
 	// UpdateWebhook 更新 Webhook
-	UpdateWebhook(webhook *Webhook) error
+	UpdateWebhook(ctx context.Context, webhook *Webhook) error
 	
 	// DeleteWebhook 删除 Webhook
-	DeleteWebhook(id string) error
+	DeleteWebhook(ctx context.Context, id string) error
 	
 	// RecordDelivery 记录投递结果
-	RecordDelivery(delivery *WebhookDelivery) error
+	RecordDelivery(ctx context.Context, delivery *WebhookDelivery) error
 	
 	// GetDeliveries 获取投递记录
-	GetDeliveries(webhookID string, limit int) ([]WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, webhookID string, limit int) ([]WebhookDelivery, error)
 	
 	// GetPendingDeliveries 获取待重试的投递
-	GetPendingDeliveries(limit int) ([]WebhookDelivery, error)
+	GetPendingDeliveries(ctx context.Context, limit int) ([]WebhookDelivery, error)
+
+	// GetDelivery 获取投递记录
+	GetDelivery(ctx context.Context, id string) (*WebhookDelivery, error)
+
+	// UpdateDelivery 更新投递记录（已重试、已重新投递）
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+
+	// ListDeadLetters 列出尚未重新投递的死信（webhookID 为空时列出全部，新的在前）
+	ListDeadLetters(ctx context.Context, webhookID string, limit int) ([]WebhookDelivery, error)
 }
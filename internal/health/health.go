@@ -1,110 +1,196 @@
 package health
 
 import (
+	"bufio"
 	"context"
-	"database/sql"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/heptiolabs/healthcheck"
 	"go.uber.org/zap"
+)
+
+// DefaultCheckTimeout 单个依赖检查的默认超时
+const DefaultCheckTimeout = 2 * time.Second
 
-	"tempmail/backend/internal/storage"
+// 依赖状态
+const (
+	StatusOK   = "ok"
+	StatusDown = "down"
 )
 
+// Check 依赖检查，返回错误表示依赖不可用
+type Check func(ctx context.Context) error
+
+// CheckResult 单个依赖的检查结果
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report 就绪检查结果
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// namedCheck 已注册的依赖检查
+type namedCheck struct {
+	name  string
+	check Check
+}
+
 // HealthChecker 健康检查器
+//
+// 存活检查只说明进程能处理请求，不检查依赖（依赖故障时重启进程无济于事，只会放大故障）；
+// 就绪检查并发探测每个依赖，任一不可用时返回 503，编排系统据此把实例摘出负载均衡。
 type HealthChecker struct {
-	health healthcheck.Handler
-	store  storage.Store
-	logger *zap.Logger
+	logger  *zap.Logger
+	timeout time.Duration
+	checks  []namedCheck
+
+	mu   sync.Mutex
+	down map[string]bool // 上次检查失败的依赖（只在状态变化时记录日志）
 }
 
 // NewHealthChecker 创建健康检查器
-func NewHealthChecker(store storage.Store, logger *zap.Logger) *HealthChecker {
-	hc := &HealthChecker{
-		health: healthcheck.NewHandler(),
-		store:  store,
-		logger: logger,
+func NewHealthChecker(logger *zap.Logger) *HealthChecker {
+	return &HealthChecker{
+		logger:  logger,
+		timeout: DefaultCheckTimeout,
+		down:    make(map[string]bool),
 	}
-
-	// 添加健康检查
-	hc.addChecks()
-
-	return hc
 }
 
-// addChecks 添加健康检查
-func (hc *HealthChecker) addChecks() {
-	// 数据库连接检查
-	hc.health.AddLivenessCheck("database", func() error {
-		return hc.store.Health()
-	})
+// AddReadinessCheck 注册就绪检查的依赖（启动时调用）
+func (hc *HealthChecker) AddReadinessCheck(name string, check Check) {
+	hc.checks = append(hc.checks, namedCheck{name: name, check: check})
+}
 
-	// Redis 连接检查（如果支持）
-	if rateLimitStore, ok := hc.store.(storage.RateLimitRepository); ok {
-		hc.health.AddLivenessCheck("redis", func() error {
-			_, err := rateLimitStore.GetRateLimit("health_check")
-			return err
-		})
+// Ready 并发执行全部就绪检查，每个检查单独计时和超时
+func (hc *HealthChecker) Ready(ctx context.Context) Report {
+	results := make([]CheckResult, len(hc.checks))
+	var wg sync.WaitGroup
+	for i, c := range hc.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = hc.run(ctx, c.check)
+		}()
 	}
+	wg.Wait()
 
-	// 系统资源检查
-	hc.health.AddLivenessCheck("system", func() error {
-		// 这里可以添加系统资源检查
-		// 例如：内存使用率、磁盘空间等
-		return nil
-	})
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(hc.checks))}
+	for i, c := range hc.checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusDown
+		}
+		hc.logTransition(c.name, results[i])
+	}
+	return report
 }
 
-// Handler 返回健康检查处理器
-func (hc *HealthChecker) Handler() http.Handler {
-	return hc.health
+// run 在超时内执行一个检查
+func (hc *HealthChecker) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{Status: StatusOK, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
 }
 
-// CheckHealth 执行健康检查
-func (hc *HealthChecker) CheckHealth() map[string]string {
-	results := make(map[string]string)
-
-	// 检查数据库
-	if err := hc.store.Health(); err != nil {
-		results["database"] = fmt.Sprintf("ERROR: %v", err)
-	} else {
-		results["database"] = "OK"
+// logTransition 依赖状态变化时记录日志（探针频繁调用，持续故障不重复记录）
+func (hc *HealthChecker) logTransition(name string, result CheckResult) {
+	down := result.Status != StatusOK
+	hc.mu.Lock()
+	changed := hc.down[name] != down
+	hc.down[name] = down
+	hc.mu.Unlock()
+	if !changed {
+		return
 	}
-
-	// 检查 Redis
-	if rateLimitStore, ok := hc.store.(storage.RateLimitRepository); ok {
-		if _, err := rateLimitStore.GetRateLimit("health_check"); err != nil {
-			results["redis"] = fmt.Sprintf("ERROR: %v", err)
-		} else {
-			results["redis"] = "OK"
-		}
+	if down {
+		hc.logger.Warn("readiness check failed", zap.String("dependency", name), zap.String("error", result.Error))
 	} else {
-		results["redis"] = "NOT_AVAILABLE"
+		hc.logger.Info("readiness check recovered", zap.String("dependency", name))
 	}
+}
 
-	// 系统状态
-	results["system"] = "OK"
-	results["timestamp"] = time.Now().Format(time.RFC3339)
+// LivenessHandler 存活检查处理器（/health/live），进程能响应即返回 200
+func (hc *HealthChecker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"status":"ok"}`)
+	})
+}
 
-	return results
+// ReadinessHandler 就绪检查处理器（/health/ready），返回每个依赖的状态，任一不可用时为 503
+func (hc *HealthChecker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := hc.Ready(r.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		body, err := json.Marshal(report)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, `{"status":"down"}`)
+			return
+		}
+		writeJSON(w, status, string(body))
+	})
 }
 
-// DatabaseHealthCheck 数据库健康检查
-func DatabaseHealthCheck(db *sql.DB) healthcheck.Check {
-	return func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+// SMTPCheck 连接 SMTP 监听地址并读取问候语，确认服务在接受连接
+//
+// addr 为监听地址（如 ":25"、"0.0.0.0:2525"），未指定主机或为通配地址时连接本机回环地址。
+func SMTPCheck(addr string) Check {
+	target := dialAddress(addr)
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", target)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
 
-		return db.PingContext(ctx)
+		greeting, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("read greeting: %w", err)
+		}
+		if !strings.HasPrefix(greeting, "220") {
+			return fmt.Errorf("unexpected greeting: %s", strings.TrimSpace(greeting))
+		}
+		_, _ = conn.Write([]byte("QUIT\r\n"))
+		return nil
 	}
 }
 
-// RedisHealthCheck Redis 健康检查
-func RedisHealthCheck(store storage.RateLimitRepository) healthcheck.Check {
-	return func() error {
-		_, err := store.GetRateLimit("health_check")
-		return err
+// dialAddress 把监听地址转换为可连接的地址
+func dialAddress(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+		if ip != nil && ip.To4() == nil {
+			host = "::1"
+		}
 	}
+	return net.JoinHostPort(host, port)
 }
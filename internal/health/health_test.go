@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// serveGreeting 在本机监听，对每个连接发送 greeting 后关闭
+func serveGreeting(t *testing.T, greeting string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte(greeting))
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestHealthChecker(t *testing.T) {
+	t.Run("依赖全部可用时就绪", func(t *testing.T) {
+		hc := NewHealthChecker(zap.NewNop())
+		hc.AddReadinessCheck("database", func(context.Context) error { return nil })
+		hc.AddReadinessCheck("redis", func(context.Context) error { return nil })
+
+		rec := httptest.NewRecorder()
+		hc.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var report Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, StatusOK, report.Status)
+		assert.Equal(t, StatusOK, report.Checks["database"].Status)
+		assert.Equal(t, StatusOK, report.Checks["redis"].Status)
+	})
+
+	t.Run("任一依赖不可用时返回 503 和各依赖状态，存活检查不受影响", func(t *testing.T) {
+		core, logs := observer.New(zapcore.InfoLevel)
+		hc := NewHealthChecker(zap.New(core))
+		hc.AddReadinessCheck("database", func(context.Context) error { return nil })
+		hc.AddReadinessCheck("redis", func(context.Context) error { return errNotReachable })
+
+		for range 3 {
+			rec := httptest.NewRecorder()
+			hc.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+			assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+			var report Report
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+			assert.Equal(t, StatusDown, report.Status)
+			assert.Equal(t, StatusOK, report.Checks["database"].Status)
+			assert.Equal(t, CheckResult{Status: StatusDown, LatencyMs: report.Checks["redis"].LatencyMs, Error: errNotReachable.Error()}, report.Checks["redis"])
+		}
+		assert.Equal(t, 1, logs.FilterMessage("readiness check failed").Len(), "持续故障只记录一次")
+
+		rec := httptest.NewRecorder()
+		hc.LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("检查超时视为不可用", func(t *testing.T) {
+		hc := NewHealthChecker(zap.NewNop())
+		hc.timeout = 10 * time.Millisecond
+		hc.AddReadinessCheck("database", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		report := hc.Ready(t.Context())
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Contains(t, report.Checks["database"].Error, "deadline exceeded")
+	})
+}
+
+func TestSMTPCheck(t *testing.T) {
+	t.Run("收到 220 问候语", func(t *testing.T) {
+		addr := serveGreeting(t, "220 mx.example ESMTP ready\r\n")
+		assert.NoError(t, SMTPCheck(addr)(t.Context()))
+	})
+
+	t.Run("问候语不是 220", func(t *testing.T) {
+		addr := serveGreeting(t, "421 shutting down\r\n")
+		assert.ErrorContains(t, SMTPCheck(addr)(t.Context()), "unexpected greeting")
+	})
+
+	t.Run("未监听", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := listener.Addr().String()
+		listener.Close()
+		assert.Error(t, SMTPCheck(addr)(t.Context()))
+	})
+
+	t.Run("通配地址连接本机", func(t *testing.T) {
+		assert.Equal(t, "127.0.0.1:25", dialAddress(":25"))
+		assert.Equal(t, "127.0.0.1:2525", dialAddress("0.0.0.0:2525"))
+		assert.Equal(t, "[::1]:25", dialAddress("[::]:25"))
+		assert.Equal(t, "10.0.0.5:25", dialAddress("10.0.0.5:25"))
+	})
+}
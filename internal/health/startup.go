@@ -0,0 +1,164 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// StartingRetryAfter 启动期间建议客户端重试的间隔（秒）
+const StartingRetryAfter = 5
+
+// ErrStartupDeadline 依赖在启动期限内仍未就绪
+var ErrStartupDeadline = errors.New("dependency not ready before startup deadline")
+
+// RetryPolicy 启动时连接依赖的退避参数
+type RetryPolicy struct {
+	Initial  time.Duration // 首次重试前等待，默认 500 毫秒
+	Max      time.Duration // 单次等待上限，默认 10 秒
+	Deadline time.Duration // 总时长，超过后放弃，默认 2 分钟
+}
+
+// permanentError 不应重试的错误
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记错误不可重试，Retry 立即返回该错误
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// Retry 按指数退避反复执行 attempt，直到成功、遇到 Permanent 错误、ctx 取消或超过期限
+//
+// 每次失败都会记录原因；超过期限时返回包装了最后一次错误的 ErrStartupDeadline。
+func Retry(ctx context.Context, log *zap.Logger, component string, policy RetryPolicy, attempt func(ctx context.Context) error) error {
+	if policy.Initial <= 0 {
+		policy.Initial = 500 * time.Millisecond
+	}
+	if policy.Max <= 0 {
+		policy.Max = 10 * time.Second
+	}
+	if policy.Deadline <= 0 {
+		policy.Deadline = 2 * time.Minute
+	}
+
+	deadline := time.Now().Add(policy.Deadline)
+	wait := policy.Initial
+	for try := 1; ; try++ {
+		err := attempt(ctx)
+		if err == nil {
+			if try > 1 {
+				log.Info("dependency ready", zap.String("component", component), zap.Int("attempts", try))
+			}
+			return nil
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w: %s after %d attempts: %v", ErrStartupDeadline, component, try, err)
+		}
+		wait = min(wait, remaining)
+		log.Warn("dependency not ready, retrying",
+			zap.String("component", component),
+			zap.Int("attempt", try),
+			zap.Duration("retry_in", wait),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		wait = min(wait*2, policy.Max)
+	}
+}
+
+// Gate 启动闸门
+//
+// 依赖就绪前 HTTP 服务已经监听：存活检查返回 200，就绪检查和 /health 返回 503，
+// /metrics 照常输出，其余请求一律 503 并附带 Retry-After。Open 之后全部交给完整路由。
+type Gate struct {
+	metrics http.Handler
+	handler atomic.Pointer[http.Handler]
+	ready   chan struct{}
+	once    sync.Once
+}
+
+// NewGate 创建启动闸门，metrics 为空时 /metrics 同样返回 503
+func NewGate(metrics http.Handler) *Gate {
+	return &Gate{metrics: metrics, ready: make(chan struct{})}
+}
+
+// Open 依赖就绪，之后的请求交给 handler；只有第一次调用生效
+func (g *Gate) Open(handler http.Handler) {
+	g.once.Do(func() {
+		g.handler.Store(&handler)
+		close(g.ready)
+	})
+}
+
+// Ready 返回依赖就绪时关闭的通道
+func (g *Gate) Ready() <-chan struct{} {
+	return g.ready
+}
+
+// IsReady 依赖是否已就绪
+func (g *Gate) IsReady() bool {
+	return g.handler.Load() != nil
+}
+
+// ServeHTTP 实现 http.Handler
+func (g *Gate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler := g.handler.Load(); handler != nil {
+		(*handler).ServeHTTP(w, r)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/health/live":
+		writeJSON(w, http.StatusOK, `{"status":"starting"}`)
+		return
+	case "/health", "/health/ready":
+		writeJSON(w, http.StatusServiceUnavailable, `{"status":"starting"}`)
+		return
+	case "/metrics":
+		if g.metrics != nil {
+			g.metrics.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	// 与 middleware.RespondThrottled 的格式一致（兼容 API 沿用上游的 {"error": ...}）
+	w.Header().Set("Retry-After", strconv.Itoa(StartingRetryAfter))
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		writeJSON(w, http.StatusServiceUnavailable, fmt.Sprintf(`{"error":"服务正在启动，请稍后重试","errorCode":"TRY_LATER","retryAfterSeconds":%d}`, StartingRetryAfter))
+		return
+	}
+	writeJSON(w, http.StatusServiceUnavailable, fmt.Sprintf(`{"code":503,"msg":"服务正在启动，请稍后重试","data":{"starting":true,"errorCode":"TRY_LATER","retryAfterSeconds":%d}}`, StartingRetryAfter))
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
@@ -0,0 +1,154 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var errNotReachable = errors.New("connection refused")
+
+// flakyConstructor 前 failures 次失败，之后成功
+func flakyConstructor(failures int32) (func(context.Context) error, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	return func(context.Context) error {
+		if calls.Add(1) <= failures {
+			return errNotReachable
+		}
+		return nil
+	}, calls
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{Initial: time.Millisecond, Max: 5 * time.Millisecond, Deadline: time.Second}
+
+	t.Run("失败两次后成功", func(t *testing.T) {
+		core, logs := observer.New(zapcore.InfoLevel)
+		attempt, calls := flakyConstructor(2)
+
+		require.NoError(t, Retry(t.Context(), zap.New(core), "database", policy, attempt))
+		assert.Equal(t, int32(3), calls.Load())
+		retries := logs.FilterMessage("dependency not ready, retrying").All()
+		require.Len(t, retries, 2)
+		assert.Equal(t, "connection refused", retries[0].ContextMap()["error"])
+		assert.Equal(t, 1, logs.FilterMessage("dependency ready").Len())
+	})
+
+	t.Run("超过期限返回 ErrStartupDeadline", func(t *testing.T) {
+		attempt, _ := flakyConstructor(1 << 30)
+		err := Retry(t.Context(), zap.NewNop(), "database", RetryPolicy{Initial: time.Millisecond, Deadline: 20 * time.Millisecond}, attempt)
+		assert.ErrorIs(t, err, ErrStartupDeadline)
+		assert.Contains(t, err.Error(), "connection refused")
+	})
+
+	t.Run("不可重试的错误立即返回", func(t *testing.T) {
+		calls := 0
+		err := Retry(t.Context(), zap.NewNop(), "database", policy, func(context.Context) error {
+			calls++
+			return Permanent(errNotReachable)
+		})
+		assert.Equal(t, errNotReachable, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("关闭信号中止等待", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		attempt, _ := flakyConstructor(1 << 30)
+		err := Retry(ctx, zap.NewNop(), "database", RetryPolicy{Initial: time.Hour, Deadline: time.Hour}, attempt)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestGate(t *testing.T) {
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("metrics")) })
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("api")) })
+
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("就绪前只放行存活检查和指标", func(t *testing.T) {
+		gate := NewGate(metrics)
+		assert.False(t, gate.IsReady())
+
+		assert.Equal(t, http.StatusOK, get(gate, "/health/live").Code)
+		assert.Equal(t, "metrics", get(gate, "/metrics").Body.String())
+		assert.Equal(t, http.StatusServiceUnavailable, get(gate, "/health/ready").Code)
+		assert.Equal(t, http.StatusServiceUnavailable, get(gate, "/health").Code)
+
+		w := get(gate, "/v1/mailboxes")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "5", w.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"code":503,"msg":"服务正在启动，请稍后重试","data":{"starting":true,"errorCode":"TRY_LATER","retryAfterSeconds":5}}`, w.Body.String())
+
+		w = get(gate, "/api/emails")
+		assert.Equal(t, "5", w.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"error":"服务正在启动，请稍后重试","errorCode":"TRY_LATER","retryAfterSeconds":5}`, w.Body.String())
+	})
+
+	t.Run("Open 后交给完整路由", func(t *testing.T) {
+		gate := NewGate(metrics)
+		gate.Open(api)
+		gate.Open(metrics) // 只有第一次生效
+
+		assert.True(t, gate.IsReady())
+		assert.Equal(t, "api", get(gate, "/v1/mailboxes").Body.String())
+		assert.Equal(t, "api", get(gate, "/health/ready").Body.String())
+		select {
+		case <-gate.Ready():
+		default:
+			t.Fatal("Ready 通道未关闭")
+		}
+	})
+
+	t.Run("存储恢复后无需重启即就绪，SMTP 在就绪后才启动", func(t *testing.T) {
+		gate := NewGate(metrics)
+		attempt, calls := flakyConstructor(2)
+
+		var smtpStartedBeforeReady, smtpStarted atomic.Bool
+		smtpDone := make(chan struct{})
+		go func() {
+			defer close(smtpDone)
+			<-gate.Ready()
+			smtpStartedBeforeReady.Store(!gate.IsReady())
+			smtpStarted.Store(true)
+		}()
+
+		opened := make(chan error, 1)
+		go func() {
+			err := Retry(t.Context(), zap.NewNop(), "database", RetryPolicy{Initial: 10 * time.Millisecond, Deadline: time.Second}, func(ctx context.Context) error {
+				err := attempt(ctx)
+				if err != nil {
+					// 等待期间 API 不可用，SMTP 未监听
+					assert.Equal(t, http.StatusServiceUnavailable, get(gate, "/v1/mailboxes").Code)
+					assert.False(t, smtpStarted.Load())
+				}
+				return err
+			})
+			if err == nil {
+				gate.Open(api)
+			}
+			opened <- err
+		}()
+
+		require.NoError(t, <-opened)
+		<-smtpDone
+		assert.Equal(t, int32(3), calls.Load())
+		assert.True(t, smtpStarted.Load())
+		assert.False(t, smtpStartedBeforeReady.Load())
+		assert.Equal(t, "api", get(gate, "/v1/mailboxes").Body.String())
+	})
+}
@@ -0,0 +1,390 @@
+// Package htmltext 把邮件 HTML 转换为可读的纯文本。
+//
+// 用于只有 HTML 正文的邮件：生成的文本作为预览、搜索和语言检测的输入。
+// 基于 net/html 分词器逐个 token 处理，不构建 DOM，畸形或未闭合的标签不会导致失败。
+package htmltext
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// MaxLength Render 输出的最大字符数
+const MaxLength = 100000
+
+// skipTags 不输出文本的标签
+var skipTags = map[string]bool{
+	"head": true, "title": true, "script": true, "style": true,
+	"noscript": true, "template": true, "svg": true,
+}
+
+// paragraphTags 前后空一行的块级标签
+var paragraphTags = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "table": true, "blockquote": true, "pre": true,
+}
+
+// lineTags 前后换行的块级标签
+var lineTags = map[string]bool{
+	"div": true, "section": true, "article": true, "header": true, "footer": true,
+	"main": true, "nav": true, "aside": true, "center": true, "address": true,
+	"dl": true, "dt": true, "dd": true, "figure": true, "figcaption": true,
+	"form": true, "fieldset": true, "tbody": true, "thead": true, "tfoot": true,
+}
+
+// Render 把 HTML 转换为纯文本（最多 MaxLength 个字符）
+func Render(htmlContent string) string {
+	return RenderLimit(htmlContent, MaxLength)
+}
+
+// RenderLimit 把 HTML 转换为纯文本，输出超过 limit 个字符时截断（limit <= 0 表示不限制）
+//
+// 块级元素换行，列表项加项目符号，链接输出为 "文字 (地址)"，表格每行一行、单元格以 " | " 分隔；
+// 实体已解码，连续空白折叠，脚本、样式和 head 中的内容被丢弃。
+func RenderLimit(htmlContent string, limit int) string {
+	r := &renderer{limit: limit}
+	tokenizer := html.NewTokenizer(strings.NewReader(htmlContent))
+	for !r.full() {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+		switch tt {
+		case html.StartTagToken:
+			r.start(token, false)
+		case html.SelfClosingTagToken:
+			r.start(token, true)
+		case html.EndTagToken:
+			r.end(token.Data)
+		case html.TextToken:
+			r.text(token.Data)
+		}
+	}
+	return r.String()
+}
+
+// renderer 输出状态
+type renderer struct {
+	b         strings.Builder
+	runes     int
+	limit     int
+	truncated bool
+
+	skipDepth int
+	preDepth  int
+	space     bool // 下一段文字前需要空格
+	newlines  int  // 输出末尾连续的换行数
+
+	lists  []listState
+	tables []rowState
+	links  []linkState
+}
+
+type listState struct {
+	ordered bool
+	index   int
+}
+
+type rowState struct {
+	cells     int  // 本行已输出文字的单元格数
+	cellText  bool // 当前单元格是否已输出文字
+	separator bool // 当前单元格输出文字前需要分隔符
+}
+
+type linkState struct {
+	href  string
+	start int // 链接文字在输出中的起始位置
+}
+
+func (r *renderer) full() bool {
+	return r.truncated
+}
+
+func (r *renderer) start(token html.Token, selfClosing bool) {
+	name := token.Data
+	if skipTags[name] {
+		if !selfClosing {
+			r.skipDepth++
+		}
+		return
+	}
+	if r.skipDepth > 0 {
+		return
+	}
+
+	switch {
+	case name == "br":
+		r.newline(1)
+	case name == "hr":
+		r.newline(1)
+		r.write("---")
+		r.newline(1)
+	case name == "li":
+		r.newline(1)
+		bullet := "• "
+		if n := len(r.lists); n > 0 {
+			list := &r.lists[n-1]
+			list.index++
+			if list.ordered {
+				bullet = strconv.Itoa(list.index) + ". "
+			}
+			bullet = strings.Repeat("  ", n-1) + bullet
+		}
+		r.write(bullet)
+		r.space = false
+	case name == "tr":
+		r.newline(1)
+		if n := len(r.tables); n > 0 {
+			r.tables[n-1] = rowState{}
+		}
+	case name == "td" || name == "th":
+		if n := len(r.tables); n > 0 {
+			row := &r.tables[n-1]
+			row.cellText = false
+			row.separator = row.cells > 0
+		}
+	case (name == "ul" || name == "ol") && len(r.lists) > 0:
+		r.newline(1) // 嵌套列表不空行
+	case name == "a":
+		if !selfClosing {
+			r.links = append(r.links, linkState{href: attr(token, "href"), start: r.b.Len()})
+		}
+	case name == "img":
+		// 只在链接中输出替代文字（图片按钮），其余图片忽略
+		if alt := strings.TrimSpace(attr(token, "alt")); alt != "" && len(r.links) > 0 {
+			r.text(alt)
+		}
+	case paragraphTags[name]:
+		r.newline(2)
+	case lineTags[name]:
+		r.newline(1)
+	}
+
+	if selfClosing {
+		return
+	}
+	switch name {
+	case "ul", "ol":
+		r.lists = append(r.lists, listState{ordered: name == "ol"})
+	case "table":
+		r.tables = append(r.tables, rowState{})
+	case "pre":
+		r.preDepth++
+	}
+}
+
+func (r *renderer) end(name string) {
+	if skipTags[name] {
+		if r.skipDepth > 0 {
+			r.skipDepth--
+		}
+		return
+	}
+	if r.skipDepth > 0 {
+		return
+	}
+
+	switch name {
+	case "ul", "ol":
+		if n := len(r.lists); n > 0 {
+			r.lists = r.lists[:n-1]
+		}
+	case "table":
+		if n := len(r.tables); n > 0 {
+			r.tables = r.tables[:n-1]
+		}
+	case "pre":
+		if r.preDepth > 0 {
+			r.preDepth--
+		}
+	case "a":
+		if n := len(r.links); n > 0 {
+			link := r.links[n-1]
+			r.links = r.links[:n-1]
+			r.linkTarget(link)
+		}
+	}
+
+	switch {
+	case name == "li" || name == "tr":
+		r.newline(1)
+	case (name == "ul" || name == "ol") && len(r.lists) > 0:
+		r.newline(1)
+	case paragraphTags[name]:
+		r.newline(2)
+	case lineTags[name]:
+		r.newline(1)
+	}
+}
+
+// linkTarget 在链接文字后追加地址（文字为空或与地址相同时省略）
+func (r *renderer) linkTarget(link linkState) {
+	href := strings.TrimSpace(link.href)
+	lower := strings.ToLower(href)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "mailto:") {
+		return
+	}
+	if link.start > r.b.Len() {
+		return
+	}
+	text := strings.TrimPrefix(strings.TrimSpace(r.b.String()[link.start:]), "| ")
+	if text == "" {
+		return
+	}
+	bare := href
+	if strings.HasPrefix(lower, "mailto:") {
+		bare = href[len("mailto:"):]
+	}
+	if text == href || text == bare || strings.TrimRight(text, "/") == strings.TrimRight(href, "/") {
+		return
+	}
+	r.space = true
+	r.write("(" + href + ")")
+}
+
+func (r *renderer) text(data string) {
+	if r.skipDepth > 0 {
+		return
+	}
+	data = stripInvisible(data)
+	if r.preDepth > 0 {
+		r.writePre(data)
+		return
+	}
+
+	leading := data != "" && unicode.IsSpace(firstRune(data))
+	trailing := data != "" && unicode.IsSpace(lastRune(data))
+	words := strings.Fields(data)
+	if len(words) == 0 {
+		if leading {
+			r.space = true
+		}
+		return
+	}
+	if leading {
+		r.space = true
+	}
+	r.write(strings.Join(words, " "))
+	r.space = trailing
+}
+
+// writePre 保留 pre 中的换行，只去掉行尾空白
+func (r *renderer) writePre(data string) {
+	lines := strings.Split(data, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			r.emit("\n")
+			r.newlines++
+		}
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line != "" {
+			r.write(line)
+		}
+	}
+	r.space = false
+}
+
+// write 输出一段文字，按需先写空格或单元格分隔符
+func (r *renderer) write(s string) {
+	if r.truncated || s == "" {
+		return
+	}
+	prefix := ""
+	if n := len(r.tables); n > 0 {
+		row := &r.tables[n-1]
+		if !row.cellText {
+			row.cellText = true
+			row.cells++
+			if row.separator && r.newlines == 0 {
+				prefix = " | "
+				r.space = false
+			}
+		}
+	}
+	if prefix == "" && r.space && r.newlines == 0 && r.b.Len() > 0 {
+		prefix = " "
+	}
+	r.space = false
+	r.emit(prefix + s)
+	r.newlines = 0
+}
+
+// newline 保证输出末尾至少有 n 个换行（最多空一行）
+func (r *renderer) newline(n int) {
+	if r.b.Len() == 0 {
+		return
+	}
+	for r.newlines < n && !r.truncated {
+		r.emit("\n")
+		r.newlines++
+	}
+	r.space = false
+}
+
+// emit 追加文字，超过上限时按字符截断
+func (r *renderer) emit(s string) {
+	if r.limit <= 0 {
+		r.b.WriteString(s)
+		return
+	}
+	count := utf8.RuneCountInString(s)
+	if r.runes+count <= r.limit {
+		r.b.WriteString(s)
+		r.runes += count
+		return
+	}
+	remaining := r.limit - r.runes
+	for _, c := range s {
+		if remaining <= 0 {
+			break
+		}
+		r.b.WriteRune(c)
+		remaining--
+	}
+	r.runes = r.limit
+	r.truncated = true
+}
+
+// String 返回最终文本：去掉行尾空白和首尾空行
+func (r *renderer) String() string {
+	lines := strings.Split(r.b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// stripInvisible 去掉营销邮件常用来填充预览的零宽字符和软连字符
+func stripInvisible(s string) string {
+	return strings.Map(func(c rune) rune {
+		switch c {
+		case '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff', '\u00ad', '\u034f':
+			return -1
+		}
+		return c
+	}, s)
+}
+
+func attr(token html.Token, key string) string {
+	for _, a := range token.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func firstRune(s string) rune {
+	c, _ := utf8.DecodeRuneInString(s)
+	return c
+}
+
+func lastRune(s string) rune {
+	c, _ := utf8.DecodeLastRuneInString(s)
+	return c
+}
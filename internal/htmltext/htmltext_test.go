@@ -0,0 +1,96 @@
+package htmltext
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRender_Corpus(t *testing.T) {
+	t.Run("营销邮件的嵌套表格", func(t *testing.T) {
+		text := Render(readFixture(t, "marketing.html"))
+		assert.Contains(t, text, "Spring Sale & More")
+		assert.Contains(t, text, "Running shoes | €59.00\nRain jacket | €89.00")
+		assert.Contains(t, text, "• Free shipping\n• 30-day returns")
+		assert.Contains(t, text, "Shop now (https://shop.example.com/sale?utm_source=mail)")
+		assert.Contains(t, text, "Unsubscribe (https://shop.example.com/unsubscribe)")
+		assert.NotContains(t, text, "trackOpen", "脚本内容被丢弃")
+		assert.NotContains(t, text, "padding", "样式内容被丢弃")
+		assert.NotContains(t, text, "‌", "零宽字符被去掉")
+		assert.NotContains(t, text, "\n\n\n", "最多空一行")
+	})
+
+	t.Run("整封邮件是一个链接", func(t *testing.T) {
+		text := Render(readFixture(t, "password_reset.html"))
+		assert.True(t, strings.HasPrefix(text, "Reset your password\n\nSomeone asked"), text)
+		assert.True(t, strings.HasSuffix(text, "(https://accounts.example.com/reset?token=abc123)"), text)
+	})
+
+	t.Run("未闭合的标签", func(t *testing.T) {
+		text := Render(readFixture(t, "malformed.html"))
+		assert.Contains(t, text, "First paragraph bold nested")
+		assert.Contains(t, text, "Second paragraph with a link")
+		assert.Contains(t, text, "cell one | cell two")
+		assert.Contains(t, text, "• orphan item")
+		assert.NotContains(t, text, "hidden")
+	})
+
+	t.Run("非拉丁文字不乱码", func(t *testing.T) {
+		text := Render(readFixture(t, "cjk.html"))
+		assert.True(t, utf8.ValidString(text))
+		assert.Contains(t, text, "您的验证码是 482913，10 分钟内有效。")
+		assert.Contains(t, text, "こんにちは、ご登録ありがとうございます。")
+		assert.Contains(t, text, "Ваш код подтверждения: 482913.")
+		assert.Contains(t, text, "🔒 请勿将验证码告诉他人。")
+		assert.NotContains(t, text, "验证码\n\n您好", "title 在 head 中，不输出")
+	})
+}
+
+func TestRender_Structure(t *testing.T) {
+	cases := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"实体解码和空白折叠", "<p>Tom&nbsp;&amp;   Jerry\n\n &lt;3</p>", "Tom & Jerry <3"},
+		{"换行", "line one<br>line two<br/>line three", "line one\nline two\nline three"},
+		{"有序列表", "<ol><li>first</li><li>second</li></ol>", "1. first\n2. second"},
+		{"嵌套列表缩进", "<ul><li>a<ul><li>b</li></ul></li></ul>", "• a\n  • b"},
+		{"链接文字就是地址时不重复", `<a href="https://example.com">https://example.com</a>`, "https://example.com"},
+		{"邮件地址链接", `<a href="mailto:help@example.com">help@example.com</a>`, "help@example.com"},
+		{"忽略非 HTTP 链接", `<a href="javascript:void(0)">Open</a> <a href="#top">Top</a>`, "Open Top"},
+		{"图片按钮使用替代文字", `<a href="https://example.com/go"><img alt="Confirm"></a>`, "Confirm (https://example.com/go)"},
+		{"行内标签不加空格", "<b>Hel</b><i>lo</i> world", "Hello world"},
+		{"pre 保留换行", "<pre>a  b\n  c</pre>", "a  b\n  c"},
+		{"空单元格不输出分隔符", "<table><tr><td></td><td>x</td><td> </td><td>y</td></tr></table>", "x | y"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, Render(tc.html))
+		})
+	}
+}
+
+func TestRenderLimit(t *testing.T) {
+	text := RenderLimit("<p>"+strings.Repeat("验证码", 100)+"</p>", 10)
+	assert.Equal(t, 10, utf8.RuneCountInString(text))
+	assert.True(t, utf8.ValidString(text), "按字符截断")
+
+	assert.Equal(t, "", Render(""))
+	assert.Equal(t, "", Render("<script>alert(1)</script><style>p{}</style>"))
+	assert.NotPanics(t, func() {
+		Render("<<<>>><a href=<p></td></tr></table></ul></a></pre>" + strings.Repeat("<div>", 10000))
+	})
+}
@@ -0,0 +1,368 @@
+package imap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// fetchItem FETCH 数据项
+type fetchItem struct {
+	name    string   // UID、FLAGS、ENVELOPE、BODY[] 等
+	section *section // BODY[...] / BODY.PEEK[...]
+}
+
+// section BODY[...] 的部分说明（RFC 3501 6.4.5）
+type section struct {
+	label     string // 响应中的数据项名，如 BODY[HEADER]<0>
+	path      []int
+	specifier string // ""、HEADER、TEXT、MIME、HEADER.FIELDS、HEADER.FIELDS.NOT
+	fields    []string
+	peek      bool
+	partial   bool
+	offset    int
+	count     int
+}
+
+var fetchMacros = map[string][]string{
+	"ALL":  {"FLAGS", "INTERNALDATE", "RFC822.SIZE", "ENVELOPE"},
+	"FAST": {"FLAGS", "INTERNALDATE", "RFC822.SIZE"},
+	"FULL": {"FLAGS", "INTERNALDATE", "RFC822.SIZE", "ENVELOPE", "BODY"},
+}
+
+var simpleFetchItems = map[string]bool{
+	"UID": true, "FLAGS": true, "INTERNALDATE": true, "RFC822.SIZE": true, "ENVELOPE": true,
+	"BODY": true, "BODYSTRUCTURE": true, "RFC822": true, "RFC822.HEADER": true, "RFC822.TEXT": true,
+}
+
+func parseFetchItems(args []arg) ([]fetchItem, error) {
+	if len(args) == 1 && args[0].isList {
+		args = args[0].list
+	}
+	if len(args) == 0 {
+		return nil, errors.New("missing fetch items")
+	}
+	var items []fetchItem
+	for _, a := range args {
+		if a.atom == "" {
+			return nil, errors.New("invalid fetch item")
+		}
+		name := strings.ToUpper(a.atom)
+		if macro, ok := fetchMacros[name]; ok && len(args) == 1 {
+			for _, item := range macro {
+				items = append(items, fetchItem{name: item})
+			}
+			continue
+		}
+		if simpleFetchItems[name] {
+			items = append(items, fetchItem{name: name})
+			continue
+		}
+		sec, err := parseSection(a.atom)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, fetchItem{name: "BODY[]", section: sec})
+	}
+	return items, nil
+}
+
+func parseSection(atom string) (*section, error) {
+	open, end := strings.IndexByte(atom, '['), strings.LastIndexByte(atom, ']')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("unknown fetch item %s", atom)
+	}
+	sec := &section{}
+	switch strings.ToUpper(atom[:open]) {
+	case "BODY":
+	case "BODY.PEEK":
+		sec.peek = true
+	default:
+		return nil, fmt.Errorf("unknown fetch item %s", atom)
+	}
+
+	spec := atom[open+1 : end]
+	head, fieldList, hasFields := strings.Cut(spec, " ")
+	if hasFields {
+		fieldList = strings.Trim(strings.TrimSpace(fieldList), "()")
+		for _, field := range strings.Fields(fieldList) {
+			sec.fields = append(sec.fields, strings.Trim(field, `"`))
+		}
+	}
+	var components []string
+	if head != "" {
+		components = strings.Split(head, ".")
+	}
+	for len(components) > 0 {
+		n, err := strconv.Atoi(components[0])
+		if err != nil {
+			break
+		}
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid section %s", spec)
+		}
+		sec.path = append(sec.path, n)
+		components = components[1:]
+	}
+	sec.specifier = strings.ToUpper(strings.Join(components, "."))
+	switch sec.specifier {
+	case "", "HEADER", "TEXT":
+	case "MIME":
+		if len(sec.path) == 0 {
+			return nil, fmt.Errorf("invalid section %s", spec)
+		}
+	case "HEADER.FIELDS", "HEADER.FIELDS.NOT":
+		if len(sec.fields) == 0 {
+			return nil, fmt.Errorf("invalid section %s", spec)
+		}
+	default:
+		return nil, fmt.Errorf("invalid section %s", spec)
+	}
+
+	sec.label = "BODY[" + spec + "]"
+	if rest := atom[end+1:]; rest != "" {
+		offset, count, ok := strings.Cut(strings.Trim(rest, "<>"), ".")
+		var err1, err2 error
+		sec.offset, err1 = strconv.Atoi(offset)
+		sec.count, err2 = strconv.Atoi(count)
+		if !ok || err1 != nil || err2 != nil || sec.offset < 0 || sec.count < 0 {
+			return nil, fmt.Errorf("invalid partial %s", rest)
+		}
+		sec.partial = true
+		sec.label += "<" + offset + ">"
+	}
+	return sec, nil
+}
+
+// content 取部分内容
+func (sec *section) content(l *loaded) []byte {
+	p := l.root
+	for _, n := range sec.path {
+		if p = p.child(n); p == nil {
+			return nil
+		}
+	}
+
+	var data []byte
+	switch sec.specifier {
+	case "":
+		if len(sec.path) == 0 {
+			data = l.content
+		} else {
+			data = p.body
+		}
+	case "MIME":
+		data = p.header
+	default:
+		// HEADER、TEXT 作用于整封邮件或 message/rfc822 部分封装的邮件
+		target := p
+		if len(sec.path) > 0 {
+			if target = p.message; target == nil {
+				return nil
+			}
+		}
+		switch sec.specifier {
+		case "HEADER":
+			data = target.header
+		case "TEXT":
+			data = target.body
+		default:
+			data = headerFields(target.header, sec.fields, sec.specifier == "HEADER.FIELDS.NOT")
+		}
+	}
+
+	if sec.partial {
+		if sec.offset >= len(data) {
+			return nil
+		}
+		data = data[sec.offset:min(sec.offset+sec.count, len(data))]
+	}
+	return data
+}
+
+func (s *session) fetch(tag string, args []arg, byUID bool) bool {
+	if len(args) < 2 || args[0].atom == "" {
+		s.tagged(tag, "BAD FETCH expects sequence set and items")
+		return true
+	}
+	set, err := parseSeqSet(args[0].atom)
+	if err != nil {
+		s.tagged(tag, "BAD %s", err)
+		return true
+	}
+	items, err := parseFetchItems(args[1:])
+	if err != nil {
+		s.tagged(tag, "BAD %s", err)
+		return true
+	}
+
+	hasUID, hasFlags, needContent, setsSeen := false, false, false, false
+	for _, item := range items {
+		switch item.name {
+		case "UID":
+			hasUID = true
+		case "FLAGS":
+			hasFlags = true
+		case "INTERNALDATE":
+		case "RFC822", "RFC822.TEXT":
+			needContent, setsSeen = true, true
+		default:
+			needContent = true
+			setsSeen = setsSeen || (item.section != nil && !item.section.peek)
+		}
+	}
+	if byUID && !hasUID {
+		items = append([]fetchItem{{name: "UID"}}, items...)
+	}
+
+	failed := 0
+	for i, e := range s.entries {
+		if !s.matches(set, i, byUID) {
+			continue
+		}
+		var l *loaded
+		if needContent {
+			if l, err = s.load(e); err != nil {
+				s.srv.log.Warn("imap load message failed", zap.String("messageId", e.id), zap.Error(err))
+				failed++
+				continue
+			}
+		}
+		flagsChanged := false
+		if setsSeen && !s.readOnly && !e.seen {
+			s.markSeen(e, true)
+			flagsChanged = true
+		}
+
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "* %d FETCH (", i+1)
+		for j, item := range items {
+			if j > 0 {
+				b.WriteByte(' ')
+			}
+			writeFetchItem(&b, item, e, l)
+		}
+		if flagsChanged && !hasFlags {
+			b.WriteString(" FLAGS " + e.flagList())
+		}
+		b.WriteString(")\r\n")
+		_, _ = s.writer.Write(b.Bytes())
+	}
+
+	if failed > 0 {
+		s.tagged(tag, "NO [UNAVAILABLE] %d messages could not be fetched", failed)
+		return true
+	}
+	s.tagged(tag, "OK FETCH completed")
+	return true
+}
+
+func writeFetchItem(b *bytes.Buffer, item fetchItem, e *entry, l *loaded) {
+	literal := func(name string, data []byte) {
+		fmt.Fprintf(b, "%s {%d}\r\n", name, len(data))
+		b.Write(data)
+	}
+	switch item.name {
+	case "UID":
+		fmt.Fprintf(b, "UID %d", e.uid)
+	case "FLAGS":
+		b.WriteString("FLAGS " + e.flagList())
+	case "INTERNALDATE":
+		fmt.Fprintf(b, `INTERNALDATE "%s"`, e.receivedAt.Format(internalDateLayout))
+	case "RFC822.SIZE":
+		fmt.Fprintf(b, "RFC822.SIZE %d", len(l.content))
+	case "ENVELOPE":
+		b.WriteString("ENVELOPE ")
+		writeEnvelope(b, l.root.fields)
+	case "BODY", "BODYSTRUCTURE":
+		b.WriteString(item.name + " ")
+		writeBodyStructure(b, l.root, item.name == "BODYSTRUCTURE")
+	case "RFC822":
+		literal("RFC822", l.content)
+	case "RFC822.HEADER":
+		literal("RFC822.HEADER", l.root.header)
+	case "RFC822.TEXT":
+		literal("RFC822.TEXT", l.root.body)
+	default:
+		literal(item.section.label, item.section.content(l))
+	}
+}
+
+func (s *session) store(tag string, args []arg, byUID bool) bool {
+	if len(args) < 3 || args[0].atom == "" || args[1].atom == "" {
+		s.tagged(tag, "BAD STORE expects sequence set, item and flags")
+		return true
+	}
+	if s.readOnly {
+		s.tagged(tag, "NO [READ-ONLY] mailbox opened with EXAMINE")
+		return true
+	}
+	if s.maintenanceRejected(tag) {
+		return true
+	}
+	set, err := parseSeqSet(args[0].atom)
+	if err != nil {
+		s.tagged(tag, "BAD %s", err)
+		return true
+	}
+	item := strings.ToUpper(args[1].atom)
+	silent := strings.HasSuffix(item, ".SILENT")
+	operation := strings.TrimSuffix(item, ".SILENT")
+	if operation != "FLAGS" && operation != "+FLAGS" && operation != "-FLAGS" {
+		s.tagged(tag, "BAD unknown STORE item %s", args[1].atom)
+		return true
+	}
+
+	flagArgs := args[2:]
+	if len(flagArgs) == 1 && flagArgs[0].isList {
+		flagArgs = flagArgs[0].list
+	}
+	flags := make(map[string]bool)
+	for _, a := range flagArgs {
+		// 不支持自定义关键字，\Recent 由服务端维护，均忽略
+		if flag, ok := systemFlags[strings.ToLower(a.atom)]; ok {
+			flags[flag] = true
+		}
+	}
+
+	for i, e := range s.entries {
+		if !s.matches(set, i, byUID) {
+			continue
+		}
+		seen := e.seen
+		switch operation {
+		case "FLAGS":
+			seen = flags[flagSeen]
+			e.flags = map[string]bool{}
+			for flag := range flags {
+				e.flags[flag] = flag != flagSeen
+			}
+		case "+FLAGS":
+			seen = seen || flags[flagSeen]
+			for flag := range flags {
+				e.flags[flag] = e.flags[flag] || flag != flagSeen
+			}
+		case "-FLAGS":
+			seen = seen && !flags[flagSeen]
+			for flag := range flags {
+				delete(e.flags, flag)
+			}
+		}
+		delete(e.flags, flagSeen)
+		s.markSeen(e, seen)
+
+		if !silent {
+			if byUID {
+				s.untagged("%d FETCH (UID %d FLAGS %s)", i+1, e.uid, e.flagList())
+			} else {
+				s.untagged("%d FETCH (FLAGS %s)", i+1, e.flagList())
+			}
+		}
+	}
+	s.tagged(tag, "OK STORE completed")
+	return true
+}
@@ -0,0 +1,315 @@
+package imap
+
+import (
+	"bufio"
+	"bytes"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tempmail/backend/internal/mailfmt"
+)
+
+// maxPartDepth MIME 嵌套的最大解析深度，更深的部分按单一部分处理
+const maxPartDepth = 16
+
+// part 解析后的 MIME 部分（整封邮件也是一个 part）
+type part struct {
+	header   []byte // 原始头部，含结尾的空行
+	body     []byte
+	fields   textproto.MIMEHeader
+	typ      string // 主类型（小写）
+	subtype  string
+	params   map[string]string
+	children []*part // multipart 的子部分
+	message  *part   // message/rfc822 封装的邮件
+}
+
+// parseMessage 解析 CRLF 换行的邮件
+func parseMessage(content []byte) *part {
+	return parsePart(content, "text/plain", 0)
+}
+
+func parsePart(data []byte, defaultType string, depth int) *part {
+	p := &part{}
+	switch {
+	case bytes.HasPrefix(data, []byte("\r\n")):
+		p.header, p.body = data[:2], data[2:]
+	default:
+		if i := bytes.Index(data, []byte("\r\n\r\n")); i >= 0 {
+			p.header, p.body = data[:i+4], data[i+4:]
+		} else {
+			p.header = data
+		}
+	}
+	p.fields, _ = textproto.NewReader(bufio.NewReader(bytes.NewReader(append(bytes.Clone(p.header), "\r\n"...)))).ReadMIMEHeader()
+	if p.fields == nil {
+		p.fields = textproto.MIMEHeader{}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(p.fields.Get("Content-Type"))
+	if err != nil || !strings.Contains(mediaType, "/") {
+		mediaType, params, _ = mime.ParseMediaType(defaultType)
+		if strings.HasPrefix(mediaType, "text/") {
+			params["charset"] = "us-ascii"
+		}
+	}
+	p.typ, p.subtype, _ = strings.Cut(mediaType, "/")
+	p.params = params
+
+	if depth >= maxPartDepth {
+		return p
+	}
+	switch {
+	case p.typ == "multipart" && params["boundary"] != "":
+		childType := "text/plain"
+		if p.subtype == "digest" {
+			childType = "message/rfc822"
+		}
+		for _, raw := range splitMultipart(p.body, params["boundary"]) {
+			p.children = append(p.children, parsePart(raw, childType, depth+1))
+		}
+		if len(p.children) == 0 {
+			p.typ, p.subtype, p.params = "text", "plain", map[string]string{"charset": "us-ascii"}
+		}
+	case p.typ == "message" && p.subtype == "rfc822":
+		p.message = parsePart(p.body, "text/plain", depth+1)
+	}
+	return p
+}
+
+// splitMultipart 按分隔线切分 multipart 正文，返回各部分的原始内容（不含分隔线前的 CRLF）
+func splitMultipart(body []byte, boundary string) [][]byte {
+	delimiter := []byte("--" + boundary)
+	var parts [][]byte
+	start := -1
+	for pos := 0; pos <= len(body); {
+		i := bytes.Index(body[pos:], delimiter)
+		if i < 0 {
+			break
+		}
+		i += pos
+		if i > 0 && !bytes.HasSuffix(body[:i], []byte("\r\n")) {
+			pos = i + len(delimiter)
+			continue
+		}
+		if start >= 0 {
+			end := i
+			if end >= 2 {
+				end -= 2
+			}
+			parts = append(parts, body[start:max(end, start)])
+		}
+		after := body[i+len(delimiter):]
+		if bytes.HasPrefix(after, []byte("--")) {
+			return parts
+		}
+		eol := bytes.Index(after, []byte("\r\n"))
+		if eol < 0 {
+			return parts
+		}
+		start = i + len(delimiter) + eol + 2
+		pos = start
+	}
+	if start >= 0 && start <= len(body) {
+		parts = append(parts, body[start:])
+	}
+	return parts
+}
+
+// child 按 IMAP 部分编号取子部分（RFC 3501 6.4.5）
+func (p *part) child(n int) *part {
+	container := p
+	if p.message != nil {
+		container = p.message
+	}
+	if len(container.children) > 0 {
+		if n >= 1 && n <= len(container.children) {
+			return container.children[n-1]
+		}
+		return nil
+	}
+	if n == 1 {
+		return container
+	}
+	return nil
+}
+
+// lines 正文行数
+func (p *part) lines() int {
+	n := bytes.Count(p.body, []byte("\r\n"))
+	if len(p.body) > 0 && !bytes.HasSuffix(p.body, []byte("\r\n")) {
+		n++
+	}
+	return n
+}
+
+// headerFields 按字段名筛选头部（not 为 true 时排除），保留原始格式并以空行结尾
+func headerFields(header []byte, names []string, not bool) []byte {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[strings.ToLower(name)] = true
+	}
+	var out bytes.Buffer
+	include := false
+	for _, line := range bytes.SplitAfter(header, []byte("\r\n")) {
+		if len(line) == 0 || bytes.Equal(line, []byte("\r\n")) {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			name, _, _ := bytes.Cut(line, []byte(":"))
+			include = wanted[strings.ToLower(strings.TrimSpace(string(name)))] != not
+		}
+		if include {
+			out.Write(line)
+		}
+	}
+	out.WriteString("\r\n")
+	return out.Bytes()
+}
+
+// writeEnvelope 输出 ENVELOPE（RFC 3501 7.4.2）
+func writeEnvelope(b *bytes.Buffer, fields textproto.MIMEHeader) {
+	from := fields.Get("From")
+	sender, replyTo := fields.Get("Sender"), fields.Get("Reply-To")
+	if sender == "" {
+		sender = from
+	}
+	if replyTo == "" {
+		replyTo = from
+	}
+
+	b.WriteByte('(')
+	writeNString(b, fields.Get("Date"))
+	b.WriteByte(' ')
+	writeNString(b, fields.Get("Subject"))
+	for _, list := range []string{from, sender, replyTo, fields.Get("To"), fields.Get("Cc"), fields.Get("Bcc")} {
+		b.WriteByte(' ')
+		writeAddressList(b, list)
+	}
+	b.WriteByte(' ')
+	writeNString(b, fields.Get("In-Reply-To"))
+	b.WriteByte(' ')
+	writeNString(b, fields.Get("Message-Id"))
+	b.WriteByte(')')
+}
+
+func writeAddressList(b *bytes.Buffer, value string) {
+	if value == "" {
+		b.WriteString("NIL")
+		return
+	}
+	addresses, err := mail.ParseAddressList(value)
+	if err != nil || len(addresses) == 0 {
+		b.WriteString("NIL")
+		return
+	}
+	b.WriteByte('(')
+	for _, address := range addresses {
+		local, host := address.Address, ""
+		if i := strings.LastIndexByte(local, '@'); i >= 0 {
+			local, host = local[:i], local[i+1:]
+		}
+		b.WriteByte('(')
+		writeNString(b, mailfmt.EncodeWord(address.Name))
+		b.WriteString(" NIL ")
+		writeNString(b, local)
+		b.WriteByte(' ')
+		writeNString(b, host)
+		b.WriteByte(')')
+	}
+	b.WriteByte(')')
+}
+
+// writeBodyStructure 输出 BODY（extended 为 false）或 BODYSTRUCTURE
+func writeBodyStructure(b *bytes.Buffer, p *part, extended bool) {
+	b.WriteByte('(')
+	if len(p.children) > 0 {
+		for _, child := range p.children {
+			writeBodyStructure(b, child, extended)
+		}
+		b.WriteByte(' ')
+		writeString(b, strings.ToUpper(p.subtype))
+		if extended {
+			b.WriteByte(' ')
+			writeParams(b, p.params)
+			b.WriteByte(' ')
+			writeDisposition(b, p.fields)
+			b.WriteString(" NIL")
+		}
+		b.WriteByte(')')
+		return
+	}
+
+	writeString(b, strings.ToUpper(p.typ))
+	b.WriteByte(' ')
+	writeString(b, strings.ToUpper(p.subtype))
+	b.WriteByte(' ')
+	writeParams(b, p.params)
+	b.WriteByte(' ')
+	writeNString(b, p.fields.Get("Content-Id"))
+	b.WriteByte(' ')
+	writeNString(b, p.fields.Get("Content-Description"))
+	b.WriteByte(' ')
+	encoding := strings.ToUpper(strings.TrimSpace(p.fields.Get("Content-Transfer-Encoding")))
+	if encoding == "" {
+		encoding = "7BIT"
+	}
+	writeString(b, encoding)
+	b.WriteString(" " + strconv.Itoa(len(p.body)))
+
+	switch {
+	case p.message != nil:
+		b.WriteByte(' ')
+		writeEnvelope(b, p.message.fields)
+		b.WriteByte(' ')
+		writeBodyStructure(b, p.message, extended)
+		b.WriteString(" " + strconv.Itoa(p.lines()))
+	case p.typ == "text":
+		b.WriteString(" " + strconv.Itoa(p.lines()))
+	}
+	if extended {
+		b.WriteString(" NIL ")
+		writeDisposition(b, p.fields)
+		b.WriteString(" NIL")
+	}
+	b.WriteByte(')')
+}
+
+func writeParams(b *bytes.Buffer, params map[string]string) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		b.WriteString("NIL")
+		return
+	}
+	sort.Strings(keys)
+	b.WriteByte('(')
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		writeString(b, strings.ToUpper(key))
+		b.WriteByte(' ')
+		writeString(b, mailfmt.EncodeWord(params[key]))
+	}
+	b.WriteByte(')')
+}
+
+func writeDisposition(b *bytes.Buffer, fields textproto.MIMEHeader) {
+	disposition, params, err := mime.ParseMediaType(fields.Get("Content-Disposition"))
+	if err != nil || disposition == "" {
+		b.WriteString("NIL")
+		return
+	}
+	b.WriteByte('(')
+	writeString(b, strings.ToUpper(disposition))
+	b.WriteByte(' ')
+	writeParams(b, params)
+	b.WriteByte(')')
+}
@@ -0,0 +1,300 @@
+package imap
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxCommandLength 单条命令（含内联的字面量）的最大长度
+	maxCommandLength = 64 << 10
+	// maxLiteralLength 单个字面量的最大长度（不支持 APPEND，字面量只用于登录名、密码和搜索条件）
+	maxLiteralLength = 8 << 10
+)
+
+var (
+	errLineTooLong   = errors.New("command line too long")
+	errLiteralTooBig = errors.New("literal too large")
+)
+
+// readCommand 读取一条完整命令；同步字面量 {n} 先调用 cont 发送继续请求，字面量内容转写为带引号字符串
+func readCommand(r *bufio.Reader, cont func() error) (string, error) {
+	var cmd strings.Builder
+	for {
+		line, err := readLine(r, maxCommandLength-cmd.Len())
+		if err != nil {
+			return "", err
+		}
+		n, nonSync, ok := literalSuffix(line)
+		if !ok {
+			cmd.WriteString(line)
+			return cmd.String(), nil
+		}
+		if n > maxLiteralLength {
+			return "", errLiteralTooBig
+		}
+		cmd.WriteString(line[:strings.LastIndexByte(line, '{')])
+		if !nonSync {
+			if err := cont(); err != nil {
+				return "", err
+			}
+		}
+		literal := make([]byte, n)
+		if _, err := io.ReadFull(r, literal); err != nil {
+			return "", err
+		}
+		cmd.WriteString(quote(string(literal)))
+	}
+}
+
+// readLine 读取一行（不含 CRLF）
+func readLine(r *bufio.Reader, limit int) (string, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > limit {
+			return "", errLineTooLong
+		}
+		if err == nil {
+			return strings.TrimRight(string(line), "\r\n"), nil
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return "", err
+		}
+	}
+}
+
+// literalSuffix 解析行尾的字面量长度 {n} 或 {n+}
+func literalSuffix(line string) (int, bool, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false, false
+	}
+	start := strings.LastIndexByte(line, '{')
+	if start < 0 {
+		return 0, false, false
+	}
+	spec := line[start+1 : len(line)-1]
+	nonSync := strings.HasSuffix(spec, "+")
+	n, err := strconv.Atoi(strings.TrimSuffix(spec, "+"))
+	if err != nil || n < 0 {
+		return 0, false, false
+	}
+	return n, nonSync, true
+}
+
+// quote 输出带引号字符串
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// arg 命令参数：原子、字符串或括号列表
+type arg struct {
+	atom   string
+	str    string
+	isStr  bool
+	list   []arg
+	isList bool
+}
+
+// text 原子或字符串的值
+func (a arg) text() (string, bool) {
+	if a.isList {
+		return "", false
+	}
+	if a.isStr {
+		return a.str, true
+	}
+	return a.atom, true
+}
+
+// parseArgs 解析命令参数
+//
+// 原子中的方括号部分（如 BODY[HEADER.FIELDS (FROM TO)]<0.100>）整体作为原子的一部分。
+func parseArgs(s string) ([]arg, error) {
+	p := &argParser{s: s}
+	args, err := p.parseList(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.s) {
+		return nil, fmt.Errorf("unexpected %q", p.s[p.pos])
+	}
+	return args, nil
+}
+
+type argParser struct {
+	s   string
+	pos int
+}
+
+func (p *argParser) parseList(end byte) ([]arg, error) {
+	var args []arg
+	for {
+		for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+			p.pos++
+		}
+		if p.pos >= len(p.s) {
+			if end != 0 {
+				return nil, errors.New("unterminated list")
+			}
+			return args, nil
+		}
+		switch c := p.s[p.pos]; {
+		case c == end:
+			p.pos++
+			return args, nil
+		case c == ')':
+			return nil, errors.New("unexpected )")
+		case c == '(':
+			p.pos++
+			list, err := p.parseList(')')
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg{list: list, isList: true})
+		case c == '"':
+			str, err := p.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg{str: str, isStr: true})
+		default:
+			atom, err := p.parseAtom()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg{atom: atom})
+		}
+	}
+}
+
+func (p *argParser) parseQuoted() (string, error) {
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		switch c := p.s[p.pos]; c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\\':
+			p.pos++
+			if p.pos < len(p.s) {
+				b.WriteByte(p.s[p.pos])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errors.New("unterminated string")
+}
+
+func (p *argParser) parseAtom() (string, error) {
+	start := p.pos
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '(', ')':
+			return p.s[start:p.pos], nil
+		case '[':
+			end := strings.IndexByte(p.s[p.pos:], ']')
+			if end < 0 {
+				return "", errors.New("unterminated [")
+			}
+			p.pos += end + 1
+		default:
+			p.pos++
+		}
+	}
+	return p.s[start:p.pos], nil
+}
+
+// seqRange 序号或 UID 范围，0 表示 *
+type seqRange struct {
+	start, end uint32
+}
+
+// seqSet 序号集合（如 1:3,5,7:*）
+type seqSet []seqRange
+
+func parseSeqSet(s string) (seqSet, error) {
+	if s == "" {
+		return nil, errors.New("empty sequence set")
+	}
+	var set seqSet
+	for _, item := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(item, ":")
+		start, err := parseSeqNumber(first)
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if isRange {
+			if end, err = parseSeqNumber(last); err != nil {
+				return nil, err
+			}
+		}
+		set = append(set, seqRange{start: start, end: end})
+	}
+	return set, nil
+}
+
+func parseSeqNumber(s string) (uint32, error) {
+	if s == "*" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid sequence number %q", s)
+	}
+	return uint32(n), nil
+}
+
+// contains n 是否在集合内，max 为 * 代表的值
+func (set seqSet) contains(n, max uint32) bool {
+	for _, r := range set {
+		start, end := r.start, r.end
+		if start == 0 {
+			start = max
+		}
+		if end == 0 {
+			end = max
+		}
+		if start > end {
+			start, end = end, start
+		}
+		if n >= start && n <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// isSeqSet 是否为序号集合语法（SEARCH 中区分序号集合与搜索键）
+func isSeqSet(s string) bool {
+	_, err := parseSeqSet(s)
+	return err == nil
+}
+
+// writeString 输出字符串：含换行或非 ASCII 字符时用字面量，否则用带引号字符串
+func writeString(b *bytes.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '\r' || c == '\n' || c == 0 || c >= 0x80 {
+			fmt.Fprintf(b, "{%d}\r\n%s", len(s), s)
+			return
+		}
+	}
+	b.WriteString(quote(s))
+}
+
+// writeNString 输出字符串，空值输出 NIL
+func writeNString(b *bytes.Buffer, s string) {
+	if s == "" {
+		b.WriteString("NIL")
+		return
+	}
+	writeString(b, s)
+}
@@ -0,0 +1,299 @@
+package imap
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// searchDateLayout SEARCH 日期参数的格式
+const searchDateLayout = "2-Jan-2006"
+
+var wordDecoder = new(mime.WordDecoder)
+
+// searchKey SEARCH 条件（RFC 3501 6.4.4）
+type searchKey struct {
+	name   string
+	values []string
+	set    seqSet
+	date   time.Time
+	size   int64
+	keys   []*searchKey // AND（括号列表）、NOT、OR 的子条件
+}
+
+// needsContent 是否需要读取邮件内容
+func (k *searchKey) needsContent() bool {
+	switch k.name {
+	case "BCC", "CC", "FROM", "SUBJECT", "TO", "HEADER", "BODY", "TEXT", "SENTBEFORE", "SENTON", "SENTSINCE", "LARGER", "SMALLER":
+		return true
+	}
+	for _, sub := range k.keys {
+		if sub.needsContent() {
+			return true
+		}
+	}
+	return false
+}
+
+type searchParser struct {
+	args []arg
+	pos  int
+}
+
+func (p *searchParser) next() (arg, bool) {
+	if p.pos >= len(p.args) {
+		return arg{}, false
+	}
+	a := p.args[p.pos]
+	p.pos++
+	return a, true
+}
+
+func (p *searchParser) text(name string) (string, error) {
+	a, ok := p.next()
+	if !ok {
+		return "", fmt.Errorf("%s expects an argument", name)
+	}
+	value, ok := a.text()
+	if !ok {
+		return "", fmt.Errorf("%s expects a string", name)
+	}
+	return value, nil
+}
+
+func (p *searchParser) parseKey() (*searchKey, error) {
+	a, ok := p.next()
+	if !ok {
+		return nil, errors.New("missing search key")
+	}
+	if a.isList {
+		sub := &searchParser{args: a.list}
+		keys, err := sub.parseAll()
+		if err != nil {
+			return nil, err
+		}
+		return &searchKey{name: "AND", keys: keys}, nil
+	}
+	if a.isStr {
+		return nil, fmt.Errorf("unexpected string %q", a.str)
+	}
+
+	k := &searchKey{name: strings.ToUpper(a.atom)}
+	switch k.name {
+	case "ALL", "ANSWERED", "DELETED", "DRAFT", "FLAGGED", "NEW", "OLD", "RECENT", "SEEN",
+		"UNANSWERED", "UNDELETED", "UNDRAFT", "UNFLAGGED", "UNSEEN":
+	case "BCC", "BODY", "CC", "FROM", "KEYWORD", "SUBJECT", "TEXT", "TO", "UNKEYWORD":
+		value, err := p.text(k.name)
+		if err != nil {
+			return nil, err
+		}
+		k.values = []string{value}
+	case "HEADER":
+		field, err := p.text(k.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.text(k.name)
+		if err != nil {
+			return nil, err
+		}
+		k.values = []string{field, value}
+	case "BEFORE", "ON", "SINCE", "SENTBEFORE", "SENTON", "SENTSINCE":
+		value, err := p.text(k.name)
+		if err != nil {
+			return nil, err
+		}
+		if k.date, err = time.Parse(searchDateLayout, value); err != nil {
+			return nil, fmt.Errorf("invalid date %q", value)
+		}
+	case "LARGER", "SMALLER":
+		value, err := p.text(k.name)
+		if err != nil {
+			return nil, err
+		}
+		if k.size, err = strconv.ParseInt(value, 10, 64); err != nil || k.size < 0 {
+			return nil, fmt.Errorf("invalid size %q", value)
+		}
+	case "UID":
+		value, err := p.text(k.name)
+		if err != nil {
+			return nil, err
+		}
+		if k.set, err = parseSeqSet(value); err != nil {
+			return nil, err
+		}
+	case "NOT":
+		sub, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		k.keys = []*searchKey{sub}
+	case "OR":
+		for range 2 {
+			sub, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			k.keys = append(k.keys, sub)
+		}
+	default:
+		set, err := parseSeqSet(a.atom)
+		if err != nil {
+			return nil, fmt.Errorf("unknown search key %s", a.atom)
+		}
+		k.name, k.set = "SEQ", set
+	}
+	return k, nil
+}
+
+func (p *searchParser) parseAll() ([]*searchKey, error) {
+	var keys []*searchKey
+	for p.pos < len(p.args) {
+		k, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("missing search key")
+	}
+	return keys, nil
+}
+
+func (s *session) search(tag string, args []arg, byUID bool) bool {
+	if len(args) >= 2 && strings.EqualFold(args[0].atom, "CHARSET") {
+		charset, _ := args[1].text()
+		if !strings.EqualFold(charset, "UTF-8") && !strings.EqualFold(charset, "US-ASCII") {
+			s.tagged(tag, "NO [BADCHARSET (UTF-8 US-ASCII)] unsupported charset")
+			return true
+		}
+		args = args[2:]
+	}
+	keys, err := (&searchParser{args: args}).parseAll()
+	if err != nil {
+		s.tagged(tag, "BAD %s", err)
+		return true
+	}
+	root := &searchKey{name: "AND", keys: keys}
+	needContent := root.needsContent()
+
+	var b strings.Builder
+	b.WriteString("SEARCH")
+	for i, e := range s.entries {
+		var l *loaded
+		if needContent {
+			if l, err = s.load(e); err != nil {
+				s.srv.log.Warn("imap load message failed", zap.String("messageId", e.id), zap.Error(err))
+				continue
+			}
+		}
+		if !s.evaluate(root, i, e, l) {
+			continue
+		}
+		if byUID {
+			fmt.Fprintf(&b, " %d", e.uid)
+		} else {
+			fmt.Fprintf(&b, " %d", i+1)
+		}
+	}
+	s.untagged("%s", b.String())
+	s.tagged(tag, "OK SEARCH completed")
+	return true
+}
+
+// evaluate 判断第 i 封邮件是否满足条件；l 只在条件需要内容时非空
+func (s *session) evaluate(k *searchKey, i int, e *entry, l *loaded) bool {
+	switch k.name {
+	case "AND":
+		for _, sub := range k.keys {
+			if !s.evaluate(sub, i, e, l) {
+				return false
+			}
+		}
+		return true
+	case "NOT":
+		return !s.evaluate(k.keys[0], i, e, l)
+	case "OR":
+		return s.evaluate(k.keys[0], i, e, l) || s.evaluate(k.keys[1], i, e, l)
+	case "ALL", "OLD", "UNKEYWORD":
+		return true
+	case "NEW", "RECENT", "KEYWORD":
+		// 不维护 \Recent，也不支持自定义关键字
+		return false
+	case "SEEN", "UNSEEN":
+		return e.seen == (k.name == "SEEN")
+	case "ANSWERED", "UNANSWERED":
+		return e.flags[flagAnswered] == (k.name == "ANSWERED")
+	case "DELETED", "UNDELETED":
+		return e.flags[flagDeleted] == (k.name == "DELETED")
+	case "DRAFT", "UNDRAFT":
+		return e.flags[flagDraft] == (k.name == "DRAFT")
+	case "FLAGGED", "UNFLAGGED":
+		return e.flags[flagFlagged] == (k.name == "FLAGGED")
+	case "BCC", "CC", "FROM", "SUBJECT", "TO":
+		return headerContains(l.root.fields.Values(k.name), k.values[0])
+	case "HEADER":
+		values := l.root.fields.Values(k.values[0])
+		if k.values[1] == "" {
+			return len(values) > 0
+		}
+		return headerContains(values, k.values[1])
+	case "BODY":
+		return containsFold(string(l.root.body), k.values[0])
+	case "TEXT":
+		return containsFold(string(l.content), k.values[0])
+	case "BEFORE", "ON", "SINCE":
+		return compareDay(e.receivedAt, k.date, k.name)
+	case "SENTBEFORE", "SENTON", "SENTSINCE":
+		sent, err := mail.ParseDate(l.root.fields.Get("Date"))
+		if err != nil {
+			sent = e.receivedAt
+		}
+		return compareDay(sent, k.date, strings.TrimPrefix(k.name, "SENT"))
+	case "LARGER":
+		return int64(len(l.content)) > k.size
+	case "SMALLER":
+		return int64(len(l.content)) < k.size
+	case "UID", "SEQ":
+		return s.matches(k.set, i, k.name == "UID")
+	}
+	return false
+}
+
+// compareDay 按日期比较（忽略时间和时区）
+func compareDay(t, day time.Time, op string) bool {
+	y, m, d := t.Date()
+	date := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	switch op {
+	case "BEFORE":
+		return date.Before(day)
+	case "ON":
+		return date.Equal(day)
+	default:
+		return !date.Before(day)
+	}
+}
+
+// headerContains 头部值（解码 RFC 2047 编码字）是否包含 substr
+func headerContains(values []string, substr string) bool {
+	for _, value := range values {
+		if decoded, err := wordDecoder.DecodeHeader(value); err == nil {
+			value = decoded
+		}
+		if containsFold(value, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,239 @@
+// Package imap IMAP4rev1（RFC 3501）邮箱访问服务
+//
+// 每个临时邮箱以单一 INBOX 的形式提供给 Thunderbird、Outlook 等客户端：用户名为邮箱地址，密码为邮箱令牌，
+// 或邮箱所属用户的应用专用密码（由 JWT 签名密钥和用户的应用密码代数派生，见 jwt.Manager.AppPassword）。
+//
+// 邮件 UID 即邮箱内的入库序号（Message.Seq），UIDVALIDITY 取邮箱创建时间；\Seen 对应 IsRead 并持久化，
+// 其余系统标志（\Deleted、\Flagged 等）只在会话内有效，EXPUNGE/CLOSE 时删除带 \Deleted 的邮件。
+// 不支持 APPEND、COPY 和创建其他文件夹；隔离区邮件不出现在 INBOX 中。维护只读模式下 STORE、EXPUNGE
+// 返回 NO [UNAVAILABLE]，CLOSE 不删除邮件。
+package imap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ErrServerClosed 服务已关闭，Serve/ListenAndServe 在 Shutdown 或 Close 后返回
+var ErrServerClosed = errors.New("imap: server closed")
+
+const (
+	// DefaultAddr 默认监听地址
+	DefaultAddr = ":143"
+	// DefaultIdleTimeout 默认空闲超时（RFC 3501 要求至少 30 分钟）
+	DefaultIdleTimeout = 30 * time.Minute
+	// idlePollInterval IDLE 期间检查新邮件和状态变化的间隔
+	idlePollInterval = 15 * time.Second
+	// commandTimeout 单条命令访问存储的超时
+	commandTimeout = 30 * time.Second
+	// writeTimeout 单条响应的写超时
+	writeTimeout = time.Minute
+)
+
+// MailStore 邮箱和邮件元数据（storage.Store 实现）
+type MailStore interface {
+	GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error)
+	ListMessages(ctx context.Context, mailboxID string) ([]domain.Message, error)
+	MarkMessageRead(ctx context.Context, mailboxID, messageID string) error
+	MarkMessageUnread(ctx context.Context, mailboxID, messageID string) error
+}
+
+// MessageContent 读取邮件内容（含文件系统中的原始邮件）和删除邮件（service.MessageService 实现）
+type MessageContent interface {
+	Get(ctx context.Context, mailboxID, messageID string) (*domain.Message, error)
+	Delete(ctx context.Context, mailboxID, messageID string) error
+}
+
+// MaintenanceChecker 维护模式状态（service.ConfigService 实现）
+type MaintenanceChecker interface {
+	MaintenanceState() (readOnly bool, message string)
+}
+
+// AppPasswordVerifier 校验用户的应用专用密码（auth.AppPasswordVerifier 实现，按用户当前的应用密码代数校验）
+type AppPasswordVerifier interface {
+	VerifyAppPassword(ctx context.Context, userID, password string) bool
+}
+
+// Server IMAP 服务
+type Server struct {
+	Addr        string        // 监听地址，为空时使用 DefaultAddr
+	Domain      string        // 问候语中的服务器名
+	IdleTimeout time.Duration // 空闲超时，为空时使用 DefaultIdleTimeout
+
+	store        MailStore
+	content      MessageContent
+	appPasswords AppPasswordVerifier // 可选：允许用所属用户的应用专用密码登录
+	maintenance  MaintenanceChecker  // 可选：维护只读模式下拒绝修改邮件
+	log          *zap.Logger
+	baseCtx      context.Context
+
+	closing   atomic.Bool
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	sessions  map[*session]struct{}
+	wg        sync.WaitGroup
+}
+
+// NewServer 创建 IMAP 服务
+func NewServer(store MailStore, content MessageContent, log *zap.Logger) *Server {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	return &Server{
+		store:     store,
+		content:   content,
+		log:       log,
+		baseCtx:   context.Background(),
+		listeners: make(map[net.Listener]struct{}),
+		sessions:  make(map[*session]struct{}),
+	}
+}
+
+// SetAppPasswords 设置应用专用密码校验（未设置时只能用邮箱令牌登录）
+func (s *Server) SetAppPasswords(verifier AppPasswordVerifier) {
+	s.appPasswords = verifier
+}
+
+// SetMaintenanceChecker 设置维护模式状态来源
+func (s *Server) SetMaintenanceChecker(checker MaintenanceChecker) {
+	s.maintenance = checker
+}
+
+// SetBaseContext 设置会话访问存储的基础上下文（随关闭信号取消）
+func (s *Server) SetBaseContext(ctx context.Context) {
+	s.baseCtx = ctx
+}
+
+// ListenAndServe 监听 Addr 并处理连接，关闭后返回 ErrServerClosed
+func (s *Server) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve 在 listener 上接受连接，每个连接一个会话，关闭后返回 ErrServerClosed
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closing.Load() {
+		s.mu.Unlock()
+		_ = listener.Close()
+		return ErrServerClosed
+	}
+	s.listeners[listener] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, listener)
+		s.mu.Unlock()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.closing.Load() {
+				return ErrServerClosed
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(50 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		sess := newSession(s, conn)
+		s.mu.Lock()
+		if s.closing.Load() {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+		s.sessions[sess] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.sessions, sess)
+				s.mu.Unlock()
+			}()
+			sess.serve()
+		}()
+	}
+}
+
+// Shutdown 停止接受新连接，空闲（含 IDLE 中）的会话发送 BYE 后断开，正在执行命令的会话完成当前命令后断开；
+// ctx 到期时强制关闭剩余连接
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.closing.Store(true)
+	s.mu.Lock()
+	for listener := range s.listeners {
+		_ = listener.Close()
+	}
+	for sess := range s.sessions {
+		sess.interruptIfIdle()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		_ = s.Close()
+		return ctx.Err()
+	}
+}
+
+// Close 立即关闭监听和所有连接
+func (s *Server) Close() error {
+	s.closing.Store(true)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for listener := range s.listeners {
+		_ = listener.Close()
+	}
+	for sess := range s.sessions {
+		_ = sess.conn.Close()
+	}
+	return nil
+}
+
+func (s *Server) idleTimeout() time.Duration {
+	if s.IdleTimeout > 0 {
+		return s.IdleTimeout
+	}
+	return DefaultIdleTimeout
+}
+
+// maintenanceState 维护只读模式是否开启，开启时返回维护说明
+func (s *Server) maintenanceState() (bool, string) {
+	if s.maintenance == nil {
+		return false, ""
+	}
+	readOnly, message := s.maintenance.MaintenanceState()
+	if message == "" {
+		message = "service temporarily unavailable for maintenance, try again later"
+	}
+	return readOnly, message
+}
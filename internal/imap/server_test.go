@@ -0,0 +1,392 @@
+package imap
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/filesystem"
+	"tempmail/backend/internal/storage/memory"
+)
+
+const (
+	testAddress     = "inbox@temp.example"
+	testToken       = "secret-token"
+	testUserID      = "user-1"
+	testAppPassword = "abcd-efgh-ijkl-mnop"
+)
+
+const multipartMessage = "From: \"Bob\" <b@example.com>\r\n" +
+	"To: inbox@temp.example\r\n" +
+	"Subject: second\r\n" +
+	"Date: Mon, 02 Jan 2006 15:04:05 +0000\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"sep\"\r\n" +
+	"\r\n" +
+	"--sep\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"plain body\r\n" +
+	"--sep\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>html body</p>\r\n" +
+	"--sep--\r\n"
+
+type fakeAppPasswords struct{}
+
+func (fakeAppPasswords) VerifyAppPassword(_ context.Context, userID, password string) bool {
+	return userID == testUserID && password == testAppPassword
+}
+
+// maintenanceSwitch 可切换的维护模式状态
+type maintenanceSwitch struct {
+	readOnly atomic.Bool
+}
+
+func (m *maintenanceSwitch) MaintenanceState() (bool, string) {
+	return m.readOnly.Load(), "database upgrade"
+}
+
+type testEnv struct {
+	server   *Server
+	addr     string
+	store    *memory.Store
+	messages *service.MessageService
+	ids      []string // 按投递顺序
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := memory.NewStore(time.Hour)
+	userID := testUserID
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: testAddress, LocalPart: "inbox", Domain: "temp.example",
+		Token: testToken, UserID: &userID, CreatedAt: time.Now(),
+	}))
+	fs, err := filesystem.NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	messages := service.NewMessageService(store)
+	messages.SetFilesystemStore(fs)
+
+	env := &testEnv{store: store, messages: messages}
+	env.deliver(t, "From: a@example.com\nSubject: first\n\nhello world\n")
+	env.deliver(t, multipartMessage)
+
+	env.server = NewServer(store, messages, nil)
+	env.server.Domain = "temp.example"
+	env.server.SetAppPasswords(fakeAppPasswords{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	env.addr = listener.Addr().String()
+	go func() { _ = env.server.Serve(listener) }()
+	t.Cleanup(func() { _ = env.server.Close() })
+	return env
+}
+
+func (env *testEnv) deliver(t *testing.T, raw string) {
+	t.Helper()
+	message, err := env.messages.Create(t.Context(), service.CreateMessageInput{
+		MailboxID: "mb-1", From: "sender@example.com", To: testAddress, Subject: "msg",
+		Text: "body", Raw: raw, Received: time.Now().Add(time.Duration(len(env.ids)) * time.Second),
+	})
+	require.NoError(t, err)
+	env.ids = append(env.ids, message.ID)
+}
+
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	tags int
+}
+
+func (env *testEnv) dial(t *testing.T) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", env.addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	c := &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+	require.True(t, strings.HasPrefix(c.readResponse(), "* OK [CAPABILITY IMAP4rev1"))
+	return c
+}
+
+func (env *testEnv) login(t *testing.T) *client {
+	t.Helper()
+	c := env.dial(t)
+	c.expectOK("LOGIN " + testAddress + " " + testToken)
+	return c
+}
+
+func (env *testEnv) selectInbox(t *testing.T) *client {
+	t.Helper()
+	c := env.login(t)
+	c.expectOK("SELECT INBOX")
+	return c
+}
+
+// readResponse 读取一条响应，字面量内容原样拼接在行内
+func (c *client) readResponse() string {
+	c.t.Helper()
+	var b strings.Builder
+	for {
+		line, err := c.r.ReadString('\n')
+		require.NoError(c.t, err)
+		line = strings.TrimSuffix(line, "\r\n")
+		b.WriteString(line)
+		n, _, ok := literalSuffix(line)
+		if !ok {
+			return b.String()
+		}
+		b.WriteString("\r\n")
+		literal := make([]byte, n)
+		_, err = io.ReadFull(c.r, literal)
+		require.NoError(c.t, err)
+		b.Write(literal)
+	}
+}
+
+// run 发送命令，返回未标记响应和标记响应（不含标签）
+func (c *client) run(command string) ([]string, string) {
+	c.t.Helper()
+	c.tags++
+	tag := fmt.Sprintf("a%d", c.tags)
+	_, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, command)
+	require.NoError(c.t, err)
+	var untagged []string
+	for {
+		line := c.readResponse()
+		if status, ok := strings.CutPrefix(line, tag+" "); ok {
+			return untagged, status
+		}
+		untagged = append(untagged, line)
+	}
+}
+
+func (c *client) expectOK(command string) []string {
+	c.t.Helper()
+	untagged, status := c.run(command)
+	require.True(c.t, strings.HasPrefix(status, "OK"), "%s: %s", command, status)
+	return untagged
+}
+
+func (c *client) expectNO(command string) string {
+	c.t.Helper()
+	_, status := c.run(command)
+	require.True(c.t, strings.HasPrefix(status, "NO"), "%s: %s", command, status)
+	return status
+}
+
+func TestServer(t *testing.T) {
+	t.Run("令牌或应用专用密码登录，错误密码多次失败后断开", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		c := env.dial(t)
+		c.expectOK("LOGIN " + testAddress + " " + testToken)
+
+		c = env.dial(t)
+		c.expectOK(`LOGIN "INBOX@temp.example" "` + testAppPassword + `"`)
+
+		c = env.dial(t)
+		plain := base64.StdEncoding.EncodeToString([]byte("\x00" + testAddress + "\x00" + testToken))
+		c.expectOK("AUTHENTICATE PLAIN " + plain)
+
+		c = env.dial(t)
+		_, status := c.run("SELECT INBOX")
+		assert.True(t, strings.HasPrefix(status, "BAD"), status)
+		for range maxAuthFailures - 1 {
+			assert.Contains(t, c.expectNO("LOGIN "+testAddress+" wrong"), "[AUTHENTICATIONFAILED]")
+		}
+		untagged, _ := c.run("LOGIN nobody@temp.example " + testToken)
+		assert.Equal(t, []string{"* BYE too many authentication failures"}, untagged)
+		_, err := c.r.ReadString('\n')
+		assert.Error(t, err)
+	})
+
+	t.Run("LIST 只返回 INBOX，SELECT 返回邮箱状态", func(t *testing.T) {
+		env := newTestEnv(t)
+		c := env.login(t)
+
+		assert.Equal(t, []string{`* LIST (\HasNoChildren) "/" INBOX`}, c.expectOK(`LIST "" "*"`))
+		assert.Empty(t, c.expectOK(`LIST "" "Sent"`))
+		c.expectNO("SELECT Sent")
+		c.expectNO("CREATE Archive")
+
+		untagged := c.expectOK("SELECT INBOX")
+		assert.Contains(t, untagged, "* 2 EXISTS")
+		assert.Contains(t, untagged, "* OK [UNSEEN 1] first unseen message")
+		assert.Contains(t, untagged, "* OK [UIDNEXT 3] predicted next UID")
+
+		assert.Equal(t, []string{"* STATUS INBOX (MESSAGES 2 UNSEEN 2)"}, c.expectOK("STATUS INBOX (MESSAGES UNSEEN)"))
+	})
+
+	t.Run("UID FETCH 返回标志、头部字段和结构，不标记已读", func(t *testing.T) {
+		env := newTestEnv(t)
+		c := env.selectInbox(t)
+
+		assert.Equal(t, []string{
+			`* 1 FETCH (UID 1 FLAGS ())`,
+			`* 2 FETCH (UID 2 FLAGS ())`,
+		}, c.expectOK("UID FETCH 1:* (FLAGS)"))
+
+		untagged := c.expectOK("UID FETCH 2 (BODY.PEEK[HEADER.FIELDS (SUBJECT)] BODYSTRUCTURE)")
+		require.Len(t, untagged, 1)
+		assert.Equal(t, "* 2 FETCH (UID 2 BODY[HEADER.FIELDS (SUBJECT)] {19}\r\nSubject: second\r\n\r\n BODYSTRUCTURE ("+
+			`("TEXT" "PLAIN" ("CHARSET" "utf-8") NIL NIL "7BIT" 10 1 NIL NIL NIL)`+
+			`("TEXT" "HTML" ("CHARSET" "utf-8") NIL NIL "7BIT" 16 1 NIL NIL NIL)`+
+			` "ALTERNATIVE" ("BOUNDARY" "sep") NIL NIL))`, untagged[0])
+
+		untagged = c.expectOK("FETCH 2 (BODY.PEEK[2] ENVELOPE)")
+		assert.Equal(t, `* 2 FETCH (BODY[2] {16}`+"\r\n"+`<p>html body</p> ENVELOPE ("Mon, 02 Jan 2006 15:04:05 +0000" "second" `+
+			`(("Bob" NIL "b" "example.com")) (("Bob" NIL "b" "example.com")) (("Bob" NIL "b" "example.com")) `+
+			`((NIL NIL "inbox" "temp.example")) NIL NIL NIL NIL))`, untagged[0])
+
+		message, err := env.messages.Get(t.Context(), "mb-1", env.ids[1])
+		require.NoError(t, err)
+		assert.False(t, message.IsRead)
+	})
+
+	t.Run("FETCH BODY[] 标记已读，STORE -FLAGS 取消已读", func(t *testing.T) {
+		env := newTestEnv(t)
+		c := env.selectInbox(t)
+
+		untagged := c.expectOK("FETCH 1 BODY[]")
+		require.Len(t, untagged, 1)
+		assert.Contains(t, untagged[0], "Subject: first\r\n\r\nhello world\r\n")
+		assert.True(t, strings.HasSuffix(untagged[0], `FLAGS (\Seen))`), untagged[0])
+		message, err := env.messages.Get(t.Context(), "mb-1", env.ids[0])
+		require.NoError(t, err)
+		assert.True(t, message.IsRead)
+
+		assert.Equal(t, []string{`* 1 FETCH (FLAGS ())`}, c.expectOK(`STORE 1 -FLAGS (\Seen)`))
+		message, err = env.messages.Get(t.Context(), "mb-1", env.ids[0])
+		require.NoError(t, err)
+		assert.False(t, message.IsRead)
+
+		assert.Empty(t, c.expectOK(`UID STORE 2 +FLAGS.SILENT (\Seen \Flagged)`))
+		assert.Equal(t, []string{`* 2 FETCH (UID 2 FLAGS (\Seen \Flagged))`}, c.expectOK("UID FETCH 2 FLAGS"))
+	})
+
+	t.Run("EXAMINE 只读", func(t *testing.T) {
+		env := newTestEnv(t)
+		c := env.login(t)
+		c.expectOK("EXAMINE INBOX")
+
+		untagged := c.expectOK("FETCH 1 BODY[TEXT]")
+		assert.Equal(t, []string{"* 1 FETCH (BODY[TEXT] {13}\r\nhello world\r\n)"}, untagged)
+		assert.Contains(t, c.expectNO(`STORE 1 +FLAGS (\Seen)`), "[READ-ONLY]")
+		message, err := env.messages.Get(t.Context(), "mb-1", env.ids[0])
+		require.NoError(t, err)
+		assert.False(t, message.IsRead)
+	})
+
+	t.Run("SEARCH 按标志、头部和正文过滤", func(t *testing.T) {
+		env := newTestEnv(t)
+		c := env.selectInbox(t)
+		c.expectOK(`STORE 2 +FLAGS (\Seen)`)
+
+		assert.Equal(t, []string{"* SEARCH 1"}, c.expectOK("SEARCH UNSEEN"))
+		assert.Equal(t, []string{"* SEARCH 2"}, c.expectOK(`UID SEARCH CHARSET UTF-8 BODY "HTML BODY"`))
+		assert.Equal(t, []string{"* SEARCH 1 2"}, c.expectOK(`SEARCH OR SUBJECT first HEADER Subject second`))
+		assert.Equal(t, []string{"* SEARCH 1"}, c.expectOK(`SEARCH NOT (SEEN) 1:*`))
+		assert.Equal(t, []string{"* SEARCH 2"}, c.expectOK(`SEARCH SENTON 2-Jan-2006`))
+		assert.Contains(t, c.expectNO("SEARCH CHARSET GBK ALL"), "[BADCHARSET")
+	})
+
+	t.Run("STORE \\Deleted 后 EXPUNGE 删除邮件", func(t *testing.T) {
+		env := newTestEnv(t)
+		c := env.selectInbox(t)
+
+		c.expectOK(`STORE 1 +FLAGS.SILENT (\Deleted)`)
+		assert.Equal(t, []string{"* 1 EXPUNGE"}, c.expectOK("EXPUNGE"))
+		list, err := env.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, env.ids[1], list[0].ID)
+
+		// 删除后序号前移，UID 不变
+		assert.Equal(t, []string{`* 1 FETCH (UID 2 FLAGS ())`}, c.expectOK("FETCH 1 (UID FLAGS)"))
+	})
+
+	t.Run("维护只读模式下 STORE 和 EXPUNGE 被拒绝，CLOSE 不删除", func(t *testing.T) {
+		env := newTestEnv(t)
+		maintenance := &maintenanceSwitch{}
+		env.server.SetMaintenanceChecker(maintenance)
+		c := env.selectInbox(t)
+
+		c.expectOK(`STORE 1 +FLAGS.SILENT (\Deleted)`)
+		maintenance.readOnly.Store(true)
+		assert.Contains(t, c.expectNO(`STORE 2 +FLAGS (\Seen)`), "[UNAVAILABLE] database upgrade")
+		assert.Contains(t, c.expectNO("EXPUNGE"), "[UNAVAILABLE] database upgrade")
+		c.expectOK("FETCH 1 (UID FLAGS)")
+		c.expectOK("CLOSE")
+
+		list, err := env.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Len(t, list, 2)
+		assert.False(t, list[1].IsRead)
+	})
+
+	t.Run("NOOP 推送新邮件和其他会话的已读变化", func(t *testing.T) {
+		env := newTestEnv(t)
+		c := env.selectInbox(t)
+
+		env.deliver(t, "From: c@example.com\r\nSubject: third\r\n\r\nnew\r\n")
+		require.NoError(t, env.store.MarkMessageRead(t.Context(), "mb-1", env.ids[0]))
+		assert.Equal(t, []string{
+			`* 1 FETCH (UID 1 FLAGS (\Seen))`,
+			"* 3 EXISTS",
+		}, c.expectOK("NOOP"))
+	})
+
+	t.Run("IDLE 期间推送新邮件，DONE 结束", func(t *testing.T) {
+		env := newTestEnv(t)
+		c := env.selectInbox(t)
+
+		_, err := fmt.Fprintf(c.conn, "a9 IDLE\r\n")
+		require.NoError(t, err)
+		assert.Equal(t, "+ idling", c.readResponse())
+		_, err = fmt.Fprintf(c.conn, "DONE\r\n")
+		require.NoError(t, err)
+		assert.Equal(t, "a9 OK IDLE terminated", c.readResponse())
+	})
+
+	t.Run("暂停的邮箱不能登录", func(t *testing.T) {
+		env := newTestEnv(t)
+		mailbox, err := env.store.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+		mailbox.Suspended = true
+		require.NoError(t, env.store.SaveMailbox(t.Context(), mailbox))
+
+		c := env.dial(t)
+		assert.Contains(t, c.expectNO("LOGIN "+testAddress+" "+testToken), "[AUTHORIZATIONFAILED]")
+	})
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.selectInbox(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, env.server.Shutdown(ctx))
+
+	// 空闲会话收到 BYE 后被断开，新连接被拒绝
+	assert.Equal(t, "* BYE server shutting down", c.readResponse())
+	_, err := c.r.ReadString('\n')
+	assert.Error(t, err)
+	_, err = net.DialTimeout("tcp", env.addr, 200*time.Millisecond)
+	assert.Error(t, err)
+}
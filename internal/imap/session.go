@@ -0,0 +1,780 @@
+package imap
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/mailfmt"
+	"tempmail/backend/internal/storage"
+)
+
+const (
+	// capabilities 支持的扩展
+	capabilities = "IMAP4rev1 AUTH=PLAIN SASL-IR LITERAL+ IDLE UNSELECT"
+	// maxAuthFailures 同一连接认证失败的次数上限，超过后断开
+	maxAuthFailures = 3
+	// inboxName 唯一的文件夹
+	inboxName = "INBOX"
+	// internalDateLayout INTERNALDATE 的格式
+	internalDateLayout = "02-Jan-2006 15:04:05 -0700"
+)
+
+// 会话状态（RFC 3501 第 3 节）
+type state int
+
+const (
+	stateNotAuthenticated state = iota
+	stateAuthenticated
+	stateSelected
+)
+
+// 系统标志：\Seen 持久化为已读状态，其余只在会话内有效
+const (
+	flagSeen     = `\Seen`
+	flagAnswered = `\Answered`
+	flagFlagged  = `\Flagged`
+	flagDeleted  = `\Deleted`
+	flagDraft    = `\Draft`
+)
+
+var systemFlags = map[string]string{
+	`\seen`:     flagSeen,
+	`\answered`: flagAnswered,
+	`\flagged`:  flagFlagged,
+	`\deleted`:  flagDeleted,
+	`\draft`:    flagDraft,
+}
+
+// entry 选中 INBOX 时快照的一封邮件
+type entry struct {
+	id         string
+	uid        uint32
+	seen       bool
+	flags      map[string]bool // 会话内标志
+	receivedAt time.Time
+}
+
+// flagList 按固定顺序输出的标志列表
+func (e *entry) flagList() string {
+	var flags []string
+	if e.seen {
+		flags = append(flags, flagSeen)
+	}
+	for _, flag := range []string{flagAnswered, flagFlagged, flagDeleted, flagDraft} {
+		if e.flags[flag] {
+			flags = append(flags, flag)
+		}
+	}
+	return "(" + strings.Join(flags, " ") + ")"
+}
+
+// loaded 最近一次加载的邮件内容
+type loaded struct {
+	uid     uint32
+	content []byte
+	root    *part
+}
+
+type session struct {
+	srv    *Server
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+
+	mu   sync.Mutex // 保护 idle，与 Server.Shutdown 协调
+	idle bool
+
+	state    state
+	failures int
+	mailbox  *domain.Mailbox
+	readOnly bool
+	entries  []*entry
+	cache    *loaded
+}
+
+func newSession(srv *Server, conn net.Conn) *session {
+	return &session{
+		srv:    srv,
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+	}
+}
+
+// interruptIfIdle 会话正在等待命令时让读取立即超时返回
+func (s *session) interruptIfIdle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.idle {
+		_ = s.conn.SetReadDeadline(time.Now())
+	}
+}
+
+func (s *session) serve() {
+	defer func() { _ = s.conn.Close() }()
+
+	domainName := s.srv.Domain
+	if domainName == "" {
+		domainName = "localhost"
+	}
+	s.untagged("OK [CAPABILITY %s] %s IMAP4rev1 ready", capabilities, domainName)
+	if !s.flush() {
+		return
+	}
+
+	for {
+		line, err := s.readCommand()
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case s.srv.closing.Load():
+				s.untagged("BYE server shutting down")
+			case errors.Is(err, errLineTooLong), errors.Is(err, errLiteralTooBig):
+				s.untagged("BYE %s", err)
+			case errors.As(err, &netErr) && netErr.Timeout():
+				s.untagged("BYE autologout, idle for too long")
+			}
+			s.flush()
+			return
+		}
+		keep := s.handle(line)
+		if !s.flush() || !keep {
+			return
+		}
+	}
+}
+
+// readCommand 读取一条命令；服务关闭时（包括等待期间关闭）返回错误
+func (s *session) readCommand() (string, error) {
+	if err := s.waitForInput(s.srv.idleTimeout()); err != nil {
+		return "", err
+	}
+	defer s.busy()
+	return readCommand(s.reader, func() error {
+		s.continuation("Ready for literal data")
+		if !s.flush() {
+			return net.ErrClosed
+		}
+		return nil
+	})
+}
+
+// waitForInput 设置读超时并标记为空闲；服务已在关闭时返回 ErrServerClosed
+func (s *session) waitForInput(timeout time.Duration) error {
+	s.mu.Lock()
+	_ = s.conn.SetReadDeadline(time.Now().Add(timeout))
+	s.idle = true
+	s.mu.Unlock()
+	if s.srv.closing.Load() {
+		s.busy()
+		return ErrServerClosed
+	}
+	return nil
+}
+
+func (s *session) busy() {
+	s.mu.Lock()
+	s.idle = false
+	s.mu.Unlock()
+}
+
+// handle 执行一条命令，返回 false 时结束会话
+func (s *session) handle(line string) bool {
+	tag, rest, _ := strings.Cut(line, " ")
+	if tag == "" || rest == "" {
+		s.untagged("BAD invalid command")
+		return true
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	name, params, _ := strings.Cut(rest, " ")
+	name = strings.ToUpper(name)
+	args, err := parseArgs(params)
+	if err != nil {
+		s.tagged(tag, "BAD %s", err)
+		return true
+	}
+	byUID := false
+	if name == "UID" {
+		if len(args) == 0 || args[0].atom == "" {
+			s.tagged(tag, "BAD missing UID command")
+			return true
+		}
+		name, args, byUID = strings.ToUpper(args[0].atom), args[1:], true
+		if name != "FETCH" && name != "STORE" && name != "SEARCH" && name != "COPY" && name != "MOVE" {
+			s.tagged(tag, "BAD unsupported UID command")
+			return true
+		}
+	}
+
+	switch name {
+	case "CAPABILITY":
+		s.untagged("CAPABILITY %s", capabilities)
+		s.tagged(tag, "OK CAPABILITY completed")
+		return true
+	case "NOOP", "CHECK":
+		if name == "CHECK" && s.state != stateSelected {
+			break
+		}
+		if s.state == stateSelected {
+			if err := s.refresh(); err != nil {
+				return s.storeError(tag, err)
+			}
+		}
+		s.tagged(tag, "OK %s completed", name)
+		return true
+	case "LOGOUT":
+		s.untagged("BYE logging out")
+		s.tagged(tag, "OK LOGOUT completed")
+		return false
+	case "STARTTLS":
+		s.tagged(tag, "NO STARTTLS not supported, use a TLS terminating proxy")
+		return true
+	}
+
+	if s.state == stateNotAuthenticated {
+		switch name {
+		case "LOGIN":
+			user, userOK := argText(args, 0)
+			password, passwordOK := argText(args, 1)
+			if !userOK || !passwordOK || len(args) != 2 {
+				s.tagged(tag, "BAD LOGIN expects username and password")
+				return true
+			}
+			return s.login(tag, user, password)
+		case "AUTHENTICATE":
+			return s.authenticate(tag, args)
+		default:
+			s.tagged(tag, "BAD command not allowed before authentication")
+			return true
+		}
+	}
+
+	switch name {
+	case "SELECT", "EXAMINE":
+		return s.selectInbox(tag, args, name == "EXAMINE")
+	case "LIST", "LSUB":
+		return s.list(tag, name, args)
+	case "STATUS":
+		return s.status(tag, args)
+	case "SUBSCRIBE", "UNSUBSCRIBE":
+		if mailbox, _ := argText(args, 0); strings.EqualFold(mailbox, inboxName) {
+			s.tagged(tag, "OK %s completed", name)
+		} else {
+			s.tagged(tag, "NO [NONEXISTENT] only INBOX is available")
+		}
+		return true
+	case "CREATE", "DELETE", "RENAME", "APPEND":
+		s.tagged(tag, "NO [CANNOT] only INBOX is available")
+		return true
+	case "IDLE":
+		return s.idleCommand(tag)
+	case "LOGIN", "AUTHENTICATE":
+		s.tagged(tag, "BAD already authenticated")
+		return true
+	}
+
+	if s.state != stateSelected {
+		s.tagged(tag, "BAD unknown command or no mailbox selected")
+		return true
+	}
+	switch name {
+	case "FETCH":
+		return s.fetch(tag, args, byUID)
+	case "STORE":
+		return s.store(tag, args, byUID)
+	case "SEARCH":
+		return s.search(tag, args, byUID)
+	case "EXPUNGE":
+		if s.readOnly {
+			s.tagged(tag, "NO [READ-ONLY] mailbox opened with EXAMINE")
+			return true
+		}
+		if s.maintenanceRejected(tag) {
+			return true
+		}
+		if failed := s.expunge(true); failed > 0 {
+			s.tagged(tag, "NO [SERVERBUG] %d messages not removed", failed)
+			return true
+		}
+		s.tagged(tag, "OK EXPUNGE completed")
+		return true
+	case "CLOSE":
+		if maintenance, _ := s.srv.maintenanceState(); !s.readOnly && !maintenance {
+			s.expunge(false)
+		}
+		s.unselect()
+		s.tagged(tag, "OK CLOSE completed")
+		return true
+	case "UNSELECT":
+		s.unselect()
+		s.tagged(tag, "OK UNSELECT completed")
+		return true
+	case "COPY", "MOVE":
+		s.tagged(tag, "NO [CANNOT] only INBOX is available")
+		return true
+	}
+	s.tagged(tag, "BAD unknown command")
+	return true
+}
+
+// argText 第 i 个参数的文本值
+func argText(args []arg, i int) (string, bool) {
+	if i >= len(args) {
+		return "", false
+	}
+	return args[i].text()
+}
+
+func (s *session) login(tag, user, password string) bool {
+	ctx, cancel := s.commandContext()
+	defer cancel()
+
+	address := strings.ToLower(strings.TrimSpace(user))
+	mailbox, err := s.srv.store.GetMailboxByAddress(ctx, address)
+	if err != nil && !errors.Is(err, storage.ErrMailboxNotFound) {
+		s.srv.log.Warn("imap lookup mailbox failed", zap.Error(err))
+		s.tagged(tag, "NO [UNAVAILABLE] temporary failure, try again later")
+		return true
+	}
+	if err != nil || !s.verifyPassword(ctx, mailbox, password) ||
+		(mailbox.ExpiresAt != nil && !mailbox.ExpiresAt.After(time.Now())) {
+		s.failures++
+		if s.failures >= maxAuthFailures {
+			s.untagged("BYE too many authentication failures")
+			s.tagged(tag, "NO [AUTHENTICATIONFAILED] invalid mailbox address or password")
+			return false
+		}
+		s.tagged(tag, "NO [AUTHENTICATIONFAILED] invalid mailbox address or password")
+		return true
+	}
+	if mailbox.Suspended {
+		s.tagged(tag, "NO [AUTHORIZATIONFAILED] mailbox suspended")
+		return true
+	}
+
+	s.mailbox = mailbox
+	s.state = stateAuthenticated
+	s.tagged(tag, "OK [CAPABILITY %s] authenticated", capabilities)
+	return true
+}
+
+// verifyPassword 邮箱令牌，或邮箱所属用户的应用专用密码
+func (s *session) verifyPassword(ctx context.Context, mailbox *domain.Mailbox, password string) bool {
+	if subtle.ConstantTimeCompare([]byte(mailbox.Token), []byte(password)) == 1 {
+		return true
+	}
+	return s.srv.appPasswords != nil && mailbox.UserID != nil && *mailbox.UserID != "" &&
+		s.srv.appPasswords.VerifyAppPassword(ctx, *mailbox.UserID, password)
+}
+
+// authenticate AUTHENTICATE PLAIN（RFC 4616），支持初始响应（RFC 4959）
+func (s *session) authenticate(tag string, args []arg) bool {
+	mechanism, _ := argText(args, 0)
+	if !strings.EqualFold(mechanism, "PLAIN") {
+		s.tagged(tag, "NO [CANNOT] unsupported authentication mechanism")
+		return true
+	}
+
+	response, ok := argText(args, 1)
+	if !ok {
+		s.continuation("")
+		if !s.flush() {
+			return false
+		}
+		if err := s.waitForInput(s.srv.idleTimeout()); err != nil {
+			return false
+		}
+		line, err := readLine(s.reader, maxLiteralLength)
+		s.busy()
+		if err != nil {
+			return false
+		}
+		response = strings.TrimSpace(line)
+	}
+	if response == "*" {
+		s.tagged(tag, "BAD authentication cancelled")
+		return true
+	}
+	if response == "=" {
+		response = ""
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(response)
+	fields := strings.Split(string(decoded), "\x00")
+	if err != nil || len(fields) != 3 || (fields[0] != "" && fields[0] != fields[1]) {
+		s.tagged(tag, "BAD invalid PLAIN response")
+		return true
+	}
+	return s.login(tag, fields[1], fields[2])
+}
+
+func (s *session) selectInbox(tag string, args []arg, readOnly bool) bool {
+	name, _ := argText(args, 0)
+	if !strings.EqualFold(name, inboxName) {
+		s.unselect()
+		s.tagged(tag, "NO [NONEXISTENT] only INBOX is available")
+		return true
+	}
+
+	messages, err := s.listMessages()
+	if err != nil {
+		s.unselect()
+		return s.storeError(tag, err)
+	}
+	s.entries = s.entries[:0]
+	for _, message := range messages {
+		s.entries = append(s.entries, newEntry(message))
+	}
+	s.state, s.readOnly, s.cache = stateSelected, readOnly, nil
+
+	s.untagged(`FLAGS (\Answered \Flagged \Deleted \Seen \Draft)`)
+	s.untagged(`OK [PERMANENTFLAGS (\Seen)] only \Seen is kept after logout`)
+	s.untagged("%d EXISTS", len(s.entries))
+	s.untagged("0 RECENT")
+	for i, e := range s.entries {
+		if !e.seen {
+			s.untagged("OK [UNSEEN %d] first unseen message", i+1)
+			break
+		}
+	}
+	s.untagged("OK [UIDVALIDITY %d] UIDs valid", s.uidValidity())
+	s.untagged("OK [UIDNEXT %d] predicted next UID", uidNext(messages))
+	if readOnly {
+		s.tagged(tag, "OK [READ-ONLY] EXAMINE completed")
+	} else {
+		s.tagged(tag, "OK [READ-WRITE] SELECT completed")
+	}
+	return true
+}
+
+func (s *session) unselect() {
+	s.state, s.entries, s.cache = stateAuthenticated, nil, nil
+}
+
+func (s *session) list(tag, name string, args []arg) bool {
+	reference, refOK := argText(args, 0)
+	pattern, patternOK := argText(args, 1)
+	if !refOK || !patternOK {
+		s.tagged(tag, "BAD %s expects reference and mailbox pattern", name)
+		return true
+	}
+	if pattern == "" {
+		s.untagged(`%s (\Noselect) "/" ""`, name)
+	} else if matchPattern(reference+pattern, inboxName) {
+		s.untagged(`%s (\HasNoChildren) "/" %s`, name, inboxName)
+	}
+	s.tagged(tag, "OK %s completed", name)
+	return true
+}
+
+// matchPattern LIST 通配符匹配（* 匹配任意字符，% 不匹配层级分隔符），INBOX 不区分大小写
+func matchPattern(pattern, name string) bool {
+	expr := regexp.QuoteMeta(strings.ToUpper(pattern))
+	expr = strings.NewReplacer(`\*`, ".*", "%", "[^/]*").Replace(expr)
+	matched, _ := regexp.MatchString("^"+expr+"$", name)
+	return matched
+}
+
+func (s *session) status(tag string, args []arg) bool {
+	name, _ := argText(args, 0)
+	if !strings.EqualFold(name, inboxName) {
+		s.tagged(tag, "NO [NONEXISTENT] only INBOX is available")
+		return true
+	}
+	if len(args) != 2 || !args[1].isList {
+		s.tagged(tag, "BAD STATUS expects a list of items")
+		return true
+	}
+
+	messages, err := s.listMessages()
+	if err != nil {
+		return s.storeError(tag, err)
+	}
+	unseen := 0
+	for _, message := range messages {
+		if !message.IsRead {
+			unseen++
+		}
+	}
+
+	items := make([]string, 0, len(args[1].list))
+	for _, item := range args[1].list {
+		switch key := strings.ToUpper(item.atom); key {
+		case "MESSAGES":
+			items = append(items, fmt.Sprintf("MESSAGES %d", len(messages)))
+		case "RECENT":
+			items = append(items, "RECENT 0")
+		case "UIDNEXT":
+			items = append(items, fmt.Sprintf("UIDNEXT %d", uidNext(messages)))
+		case "UIDVALIDITY":
+			items = append(items, fmt.Sprintf("UIDVALIDITY %d", s.uidValidity()))
+		case "UNSEEN":
+			items = append(items, fmt.Sprintf("UNSEEN %d", unseen))
+		default:
+			s.tagged(tag, "BAD unknown status item %s", item.atom)
+			return true
+		}
+	}
+	s.untagged("STATUS %s (%s)", inboxName, strings.Join(items, " "))
+	s.tagged(tag, "OK STATUS completed")
+	return true
+}
+
+// idleCommand IDLE（RFC 2177）：定期检查邮箱变化并推送，直到客户端发送 DONE
+func (s *session) idleCommand(tag string) bool {
+	s.continuation("idling")
+	if !s.flush() {
+		return false
+	}
+
+	deadline := time.Now().Add(s.srv.idleTimeout())
+	var pending []byte
+	for {
+		if err := s.waitForInput(min(idlePollInterval, time.Until(deadline))); err != nil {
+			s.untagged("BYE server shutting down")
+			return false
+		}
+		chunk, err := s.reader.ReadSlice('\n')
+		s.busy()
+		pending = append(pending, chunk...)
+		if err == nil {
+			if strings.EqualFold(strings.TrimSpace(string(pending)), "DONE") {
+				s.tagged(tag, "OK IDLE terminated")
+			} else {
+				s.tagged(tag, "BAD expected DONE")
+			}
+			return true
+		}
+
+		var netErr net.Error
+		switch {
+		case len(pending) > maxLiteralLength:
+			return false
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case !errors.As(err, &netErr) || !netErr.Timeout():
+			return false
+		case s.srv.closing.Load():
+			s.untagged("BYE server shutting down")
+			return false
+		case !time.Now().Before(deadline):
+			s.untagged("BYE autologout, idle for too long")
+			return false
+		}
+		if s.state == stateSelected {
+			if err := s.refresh(); err != nil {
+				s.srv.log.Warn("imap idle refresh failed", zap.Error(err))
+			}
+		}
+		if !s.flush() {
+			return false
+		}
+	}
+}
+
+// refresh 重新读取邮件列表，推送删除（EXPUNGE）、新邮件（EXISTS）和已读状态变化（FETCH FLAGS）
+func (s *session) refresh() error {
+	messages, err := s.listMessages()
+	if err != nil {
+		return err
+	}
+	current := make(map[uint32]domain.Message, len(messages))
+	for _, message := range messages {
+		current[uint32(message.Seq)] = message
+	}
+
+	// 倒序推送 EXPUNGE，保证每条的序号在推送时有效
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if _, ok := current[s.entries[i].uid]; !ok {
+			s.removeEntry(i)
+			s.untagged("%d EXPUNGE", i+1)
+		}
+	}
+	var maxUID uint32
+	for i, e := range s.entries {
+		maxUID = max(maxUID, e.uid)
+		if message := current[e.uid]; message.IsRead != e.seen {
+			e.seen = message.IsRead
+			s.untagged("%d FETCH (UID %d FLAGS %s)", i+1, e.uid, e.flagList())
+		}
+	}
+	added := false
+	for _, message := range messages {
+		if uint32(message.Seq) > maxUID {
+			s.entries = append(s.entries, newEntry(message))
+			added = true
+		}
+	}
+	if added {
+		s.untagged("%d EXISTS", len(s.entries))
+	}
+	return nil
+}
+
+// expunge 删除带 \Deleted 标志的邮件，返回删除失败的数量
+func (s *session) expunge(notify bool) int {
+	ctx, cancel := s.commandContext()
+	defer cancel()
+	failed := 0
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		if !e.flags[flagDeleted] {
+			continue
+		}
+		if err := s.srv.content.Delete(ctx, s.mailbox.ID, e.id); err != nil {
+			s.srv.log.Warn("imap delete message failed", zap.String("messageId", e.id), zap.Error(err))
+			failed++
+			continue
+		}
+		s.removeEntry(i)
+		if notify {
+			s.untagged("%d EXPUNGE", i+1)
+		}
+	}
+	return failed
+}
+
+func (s *session) removeEntry(i int) {
+	if s.cache != nil && s.cache.uid == s.entries[i].uid {
+		s.cache = nil
+	}
+	s.entries = append(s.entries[:i], s.entries[i+1:]...)
+}
+
+// listMessages 读取 INBOX 中的邮件（不含隔离区），按 UID 升序
+func (s *session) listMessages() ([]domain.Message, error) {
+	ctx, cancel := s.commandContext()
+	defer cancel()
+	messages, err := s.srv.store.ListMessages(ctx, s.mailbox.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := messages[:0]
+	for _, message := range messages {
+		if !message.Quarantined && message.Seq > 0 {
+			result = append(result, message)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Seq < result[j].Seq })
+	return result, nil
+}
+
+func newEntry(message domain.Message) *entry {
+	return &entry{
+		id:         message.ID,
+		uid:        uint32(message.Seq),
+		seen:       message.IsRead,
+		flags:      map[string]bool{},
+		receivedAt: message.ReceivedAt,
+	}
+}
+
+// uidValidity 取邮箱创建时间，邮箱删除后以同一地址重建时客户端会丢弃缓存
+func (s *session) uidValidity() uint32 {
+	return max(uint32(s.mailbox.CreatedAt.Unix()), 1)
+}
+
+func uidNext(messages []domain.Message) uint32 {
+	if len(messages) == 0 {
+		return 1
+	}
+	return uint32(messages[len(messages)-1].Seq) + 1
+}
+
+// load 经 MessageContent 读取邮件，转换为 CRLF 换行并解析 MIME 结构（缓存最近一封）
+func (s *session) load(e *entry) (*loaded, error) {
+	if s.cache != nil && s.cache.uid == e.uid {
+		return s.cache, nil
+	}
+	ctx, cancel := s.commandContext()
+	defer cancel()
+	message, err := s.srv.content.Get(ctx, s.mailbox.ID, e.id)
+	if err != nil {
+		return nil, err
+	}
+	content := mailfmt.Render(message, s.srv.Domain)
+	s.cache = &loaded{uid: e.uid, content: content, root: parseMessage(content)}
+	return s.cache, nil
+}
+
+// markSeen 持久化已读状态
+func (s *session) markSeen(e *entry, seen bool) {
+	if e.seen == seen {
+		return
+	}
+	ctx, cancel := s.commandContext()
+	defer cancel()
+	var err error
+	if seen {
+		err = s.srv.store.MarkMessageRead(ctx, s.mailbox.ID, e.id)
+	} else {
+		err = s.srv.store.MarkMessageUnread(ctx, s.mailbox.ID, e.id)
+	}
+	if err != nil {
+		// 其他会话可能已改变状态或删除邮件，下次 NOOP 时同步
+		s.srv.log.Debug("imap update seen flag failed", zap.String("messageId", e.id), zap.Error(err))
+	}
+	e.seen = seen
+}
+
+func (s *session) commandContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(s.srv.baseCtx, commandTimeout)
+}
+
+func (s *session) storeError(tag string, err error) bool {
+	s.srv.log.Warn("imap storage error", zap.Error(err))
+	s.tagged(tag, "NO [UNAVAILABLE] temporary failure, try again later")
+	return true
+}
+
+// maintenanceRejected 维护只读模式下以 NO [UNAVAILABLE] 拒绝修改邮件的命令，已回复时返回 true
+func (s *session) maintenanceRejected(tag string) bool {
+	readOnly, message := s.srv.maintenanceState()
+	if readOnly {
+		s.tagged(tag, "NO [UNAVAILABLE] %s", message)
+	}
+	return readOnly
+}
+
+func (s *session) untagged(format string, args ...any) {
+	fmt.Fprintf(s.writer, "* "+format+"\r\n", args...)
+}
+
+func (s *session) tagged(tag, format string, args ...any) {
+	fmt.Fprintf(s.writer, tag+" "+format+"\r\n", args...)
+}
+
+func (s *session) continuation(text string) {
+	fmt.Fprintf(s.writer, "+ %s\r\n", text)
+}
+
+func (s *session) flush() bool {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return s.writer.Flush() == nil
+}
+
+// matches 邮件在 INBOX 中的序号或 UID 是否在集合内
+func (s *session) matches(set seqSet, i int, byUID bool) bool {
+	if byUID {
+		var maxUID uint32
+		if len(s.entries) > 0 {
+			maxUID = s.entries[len(s.entries)-1].uid
+		}
+		return set.contains(s.entries[i].uid, maxUID)
+	}
+	return set.contains(uint32(i+1), uint32(len(s.entries)))
+}
@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// TextBackfiller 为只有 HTML 正文的邮件生成纯文本（MessageService 实现）
+type TextBackfiller interface {
+	BackfillText(ctx context.Context, message *domain.Message) (string, error)
+}
+
+// backfillPreviews 为入库时未生成纯文本的历史邮件补上由 HTML 转换的纯文本，预览和搜索随之可用
+type backfillPreviews struct {
+	scan     storage.MaintenanceScanRepository
+	messages TextBackfiller
+}
+
+// NewBackfillPreviews 创建预览回填任务
+func NewBackfillPreviews(scan storage.MaintenanceScanRepository, messages TextBackfiller) Handler {
+	return &backfillPreviews{scan: scan, messages: messages}
+}
+
+func (h *backfillPreviews) Total(ctx context.Context, params map[string]string) (int64, error) {
+	return h.scan.CountMessages(ctx)
+}
+
+func (h *backfillPreviews) Next(ctx context.Context, params map[string]string, cursor string, limit int) ([]Item, error) {
+	messages, err := h.scan.ListMessagesAfter(ctx, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(messages))
+	for _, message := range messages {
+		items = append(items, Item{
+			Key: message.ID,
+			Apply: func(ctx context.Context) (bool, error) {
+				text, err := h.messages.BackfillText(ctx, &message)
+				if err != nil || text == "" {
+					return false, err
+				}
+				return true, h.scan.SetMessageDerivedText(ctx, message.MailboxID, message.ID, text)
+			},
+		})
+	}
+	return items, nil
+}
+
+// recountMailboxes 按邮件重新统计邮箱的总数和未读数，修正增量计数的偏差
+type recountMailboxes struct {
+	scan storage.MaintenanceScanRepository
+}
+
+// NewRecountMailboxes 创建邮箱统计重算任务
+func NewRecountMailboxes(scan storage.MaintenanceScanRepository) Handler {
+	return &recountMailboxes{scan: scan}
+}
+
+func (h *recountMailboxes) Total(ctx context.Context, params map[string]string) (int64, error) {
+	return h.scan.CountMailboxes(ctx)
+}
+
+func (h *recountMailboxes) Next(ctx context.Context, params map[string]string, cursor string, limit int) ([]Item, error) {
+	ids, err := h.scan.ListMailboxIDsAfter(ctx, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, Item{
+			Key: id,
+			Apply: func(ctx context.Context) (bool, error) {
+				return h.scan.RecountMailbox(ctx, id)
+			},
+		})
+	}
+	return items, nil
+}
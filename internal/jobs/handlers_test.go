@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestHandlers(t *testing.T) {
+	store := memory.NewStore(time.Hour)
+	for _, id := range []string{"mb-1", "mb-2"} {
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+			ID: id, Address: id + "@temp.mail", LocalPart: id, Domain: "temp.mail", Token: "tok-" + id, CreatedAt: time.Now(),
+		}))
+	}
+	// 升级前入库的邮件：只有 HTML，没有生成纯文本
+	for _, msg := range []*domain.Message{
+		{ID: "m1", MailboxID: "mb-1", HasHTML: true, HTML: "<p>Your code is <b>4821</b></p>"},
+		{ID: "m2", MailboxID: "mb-1", HasText: true, Text: "plain", IsRead: true},
+		{ID: "m3", MailboxID: "mb-2", HasHTML: true, HTML: "<p>Welcome aboard</p>"},
+		{ID: "m4", MailboxID: "mb-2", HasHTML: true, HTML: "<img src=x>"},
+	} {
+		require.NoError(t, store.SaveMessage(t.Context(), msg))
+	}
+
+	runner := NewRunner(store, nil)
+	runner.Register(domain.MaintenanceJobBackfillPreviews, NewBackfillPreviews(store, service.NewMessageService(store)))
+	runner.Register(domain.MaintenanceJobRecountMailboxes, NewRecountMailboxes(store))
+	runner.SetBatchSize(2)
+	startRunner(t, runner)
+
+	t.Run("回填预览", func(t *testing.T) {
+		job, err := runner.Enqueue(t.Context(), domain.MaintenanceJobBackfillPreviews, nil, "admin-1")
+		require.NoError(t, err)
+		job = waitStatus(t, store, job.ID, domain.MaintenanceJobSucceeded)
+		assert.Equal(t, int64(4), job.Processed)
+		assert.Equal(t, int64(2), job.Updated, "已有纯文本或 HTML 没有文字的邮件不修改")
+
+		msg, err := store.GetMessage(t.Context(), "mb-1", "m1")
+		require.NoError(t, err)
+		assert.True(t, msg.HasText)
+		assert.True(t, msg.TextDerivedFromHTML)
+		assert.Contains(t, msg.Text, "4821")
+
+		plain, err := store.GetMessage(t.Context(), "mb-1", "m2")
+		require.NoError(t, err)
+		assert.False(t, plain.TextDerivedFromHTML)
+
+		again, err := runner.Enqueue(t.Context(), domain.MaintenanceJobBackfillPreviews, nil, "admin-1")
+		require.NoError(t, err)
+		again = waitStatus(t, store, again.ID, domain.MaintenanceJobSucceeded)
+		assert.Zero(t, again.Updated, "重复执行没有副作用")
+	})
+
+	t.Run("重新统计邮箱", func(t *testing.T) {
+		drifted, err := store.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+		drifted.TotalCount, drifted.Unread = 7, 5
+		require.NoError(t, store.SaveMailbox(t.Context(), drifted))
+
+		job, err := runner.Enqueue(t.Context(), domain.MaintenanceJobRecountMailboxes, nil, "admin-1")
+		require.NoError(t, err)
+		job = waitStatus(t, store, job.ID, domain.MaintenanceJobSucceeded)
+		assert.Equal(t, int64(2), job.Total)
+		assert.Equal(t, int64(2), job.Processed)
+		assert.Equal(t, int64(1), job.Updated, "统计正确的邮箱不修改")
+
+		mb, err := store.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Equal(t, 2, mb.TotalCount)
+		assert.Equal(t, 1, mb.Unread)
+	})
+}
@@ -0,0 +1,412 @@
+// Package jobs 执行管理员触发的批量维护任务（回填、重新统计等）。
+//
+// 任务按记录主键顺序分批处理：每批列出游标之后的记录，批内按并发上限和速率限制逐条处理，
+// 整批成功后保存游标和计数。服务关闭时任务保持 running，重启后从最近保存的游标继续；
+// 处理失败时任务标记为 failed，取消通过 context 协作完成，最迟在当前批结束时停止。
+package jobs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+var (
+	// ErrUnknownJobType 未注册的任务类型
+	ErrUnknownJobType = errors.New("unknown maintenance job type")
+	// ErrJobActive 同类型的任务正在排队或运行
+	ErrJobActive = storage.ErrMaintenanceJobActive
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = storage.ErrMaintenanceJobNotFound
+	// ErrJobFinished 任务已结束，不能取消
+	ErrJobFinished = errors.New("maintenance job already finished")
+)
+
+const (
+	DefaultBatchSize   = 500 // 每批处理的记录数
+	DefaultConcurrency = 4   // 批内并发处理的记录数
+)
+
+// Item 一条待处理的记录
+type Item struct {
+	Key string // 记录主键，所在批次完成后作为游标保存
+	// Apply 处理记录，返回是否修改了数据（重复执行须无副作用，失败或中断后整批会重新处理）
+	Apply func(ctx context.Context) (bool, error)
+}
+
+// Handler 一种维护任务的实现
+type Handler interface {
+	// Total 估算待扫描的记录数，用于展示进度
+	Total(ctx context.Context, params map[string]string) (int64, error)
+	// Next 按主键升序列出 cursor 之后的至多 limit 条记录，没有更多记录时返回空
+	Next(ctx context.Context, params map[string]string, cursor string, limit int) ([]Item, error)
+}
+
+// Runner 维护任务调度器
+type Runner struct {
+	store    storage.MaintenanceJobRepository
+	handlers map[domain.MaintenanceJobType]Handler
+	log      *zap.Logger
+	now      func() time.Time
+
+	batchSize   int
+	concurrency int
+	limiter     *rate.Limiter // 为空时不限速
+
+	mu      sync.Mutex
+	running map[string]*runningJob // jobID -> 本实例正在执行的任务
+	wake    chan struct{}
+}
+
+// runningJob 正在执行的任务
+type runningJob struct {
+	cancel    context.CancelFunc
+	cancelled bool // 管理员取消（区别于服务关闭）
+}
+
+// NewRunner 创建维护任务调度器
+func NewRunner(store storage.MaintenanceJobRepository, log *zap.Logger) *Runner {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	return &Runner{
+		store:       store,
+		handlers:    make(map[domain.MaintenanceJobType]Handler),
+		log:         log,
+		now:         time.Now,
+		batchSize:   DefaultBatchSize,
+		concurrency: DefaultConcurrency,
+		running:     make(map[string]*runningJob),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Register 注册任务类型
+func (r *Runner) Register(jobType domain.MaintenanceJobType, handler Handler) {
+	r.handlers[jobType] = handler
+}
+
+// Types 返回已注册的任务类型（按名称排序）
+func (r *Runner) Types() []domain.MaintenanceJobType {
+	types := make([]domain.MaintenanceJobType, 0, len(r.handlers))
+	for jobType := range r.handlers {
+		types = append(types, jobType)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// SetBatchSize 设置每批处理的记录数
+func (r *Runner) SetBatchSize(size int) {
+	if size > 0 {
+		r.batchSize = size
+	}
+}
+
+// SetConcurrency 设置批内并发处理的记录数
+func (r *Runner) SetConcurrency(n int) {
+	if n > 0 {
+		r.concurrency = n
+	}
+}
+
+// SetRateLimit 设置每秒最多处理的记录数，0 表示不限速
+func (r *Runner) SetRateLimit(perSecond float64) {
+	if perSecond <= 0 {
+		r.limiter = nil
+		return
+	}
+	r.limiter = rate.NewLimiter(rate.Limit(perSecond), max(1, int(perSecond)))
+}
+
+// SetClock 设置时间来源（测试用）
+func (r *Runner) SetClock(now func() time.Time) {
+	r.now = now
+}
+
+// Enqueue 创建任务并唤醒调度器
+func (r *Runner) Enqueue(ctx context.Context, jobType domain.MaintenanceJobType, params map[string]string, createdBy string) (*domain.MaintenanceJob, error) {
+	if _, ok := r.handlers[jobType]; !ok {
+		return nil, ErrUnknownJobType
+	}
+
+	now := r.now()
+	job := &domain.MaintenanceJob{
+		ID:        uuid.NewString(),
+		Type:      jobType,
+		Params:    params,
+		Status:    domain.MaintenanceJobQueued,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := r.store.CreateMaintenanceJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	r.log.Info("maintenance job queued",
+		zap.String("jobID", job.ID),
+		zap.String("type", string(jobType)),
+		zap.String("actor", createdBy))
+	r.notify()
+	return job, nil
+}
+
+// Get 获取任务
+func (r *Runner) Get(ctx context.Context, id string) (*domain.MaintenanceJob, error) {
+	return r.store.GetMaintenanceJob(ctx, id)
+}
+
+// List 列出任务（新任务在前）
+func (r *Runner) List(ctx context.Context) ([]*domain.MaintenanceJob, error) {
+	return r.store.ListMaintenanceJobs(ctx)
+}
+
+// Cancel 取消任务
+//
+// 本实例正在执行的任务在当前批结束前停止，已处理的批次保留；排队中或由其他实例执行的任务直接标记为已取消，
+// 执行方在下一批开始前发现后停止。
+func (r *Runner) Cancel(ctx context.Context, id string) (*domain.MaintenanceJob, error) {
+	r.mu.Lock()
+	if running, ok := r.running[id]; ok {
+		running.cancelled = true
+		running.cancel()
+		r.mu.Unlock()
+		r.log.Info("maintenance job cancellation requested", zap.String("jobID", id))
+		return r.store.GetMaintenanceJob(ctx, id)
+	}
+	r.mu.Unlock()
+
+	job, err := r.store.GetMaintenanceJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !job.Active() {
+		return nil, ErrJobFinished
+	}
+	r.finish(job, domain.MaintenanceJobCancelled, "")
+	if err := r.store.UpdateMaintenanceJob(ctx, job); err != nil {
+		return nil, err
+	}
+	r.log.Info("maintenance job cancelled", zap.String("jobID", id))
+	return job, nil
+}
+
+// notify 唤醒调度器检查新任务
+func (r *Runner) notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run 执行排队中的任务，启动时恢复上次未完成的任务，直到 ctx 取消
+//
+// 关闭时等待正在执行的任务在当前批结束后退出，任务保持 running，下次启动时从游标继续。
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	r.startPending(ctx, &wg)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+			r.startPending(ctx, &wg)
+		}
+	}
+}
+
+// startPending 启动尚未在本实例执行的活动任务
+func (r *Runner) startPending(ctx context.Context, wg *sync.WaitGroup) {
+	jobs, err := r.store.ListMaintenanceJobs(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.log.Error("failed to list maintenance jobs", zap.Error(err))
+		}
+		return
+	}
+
+	// 列表按创建时间倒序，先创建的任务先启动
+	for i := len(jobs) - 1; i >= 0; i-- {
+		job := jobs[i]
+		if !job.Active() {
+			continue
+		}
+		handler, ok := r.handlers[job.Type]
+		if !ok {
+			r.log.Warn("skipping maintenance job of unknown type", zap.String("jobID", job.ID), zap.String("type", string(job.Type)))
+			continue
+		}
+
+		r.mu.Lock()
+		if _, running := r.running[job.ID]; running {
+			r.mu.Unlock()
+			continue
+		}
+		jobCtx, cancel := context.WithCancel(ctx)
+		entry := &runningJob{cancel: cancel}
+		r.running[job.ID] = entry
+		r.mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				r.mu.Lock()
+				delete(r.running, job.ID)
+				r.mu.Unlock()
+				cancel()
+			}()
+			r.execute(ctx, jobCtx, entry, job, handler)
+		}()
+	}
+}
+
+// execute 分批执行任务直到完成、失败、取消或服务关闭
+//
+// runCtx 随服务关闭取消，jobCtx 另外随管理员取消而取消；状态写入使用不可取消的 context，确保最后的进度能保存。
+func (r *Runner) execute(runCtx, jobCtx context.Context, entry *runningJob, job *domain.MaintenanceJob, handler Handler) {
+	saveCtx := context.WithoutCancel(jobCtx)
+	log := r.log.With(zap.String("jobID", job.ID), zap.String("type", string(job.Type)))
+
+	if job.Status == domain.MaintenanceJobQueued {
+		total, err := handler.Total(jobCtx, job.Params)
+		if err != nil && jobCtx.Err() == nil {
+			log.Warn("failed to estimate maintenance job size", zap.Error(err))
+		}
+		now := r.now()
+		job.Status = domain.MaintenanceJobRunning
+		job.Total = total
+		job.StartedAt = &now
+		job.UpdatedAt = now
+		if err := r.store.UpdateMaintenanceJob(saveCtx, job); err != nil {
+			log.Error("failed to start maintenance job", zap.Error(err))
+			return
+		}
+		log.Info("maintenance job started", zap.Int64("total", total))
+	} else {
+		log.Info("maintenance job resumed", zap.String("cursor", job.Cursor), zap.Int64("processed", job.Processed))
+	}
+
+	for {
+		// 其他实例或排队时的取消直接写入存储，每批开始前检查
+		if stored, err := r.store.GetMaintenanceJob(saveCtx, job.ID); err == nil && !stored.Active() {
+			log.Info("maintenance job stopped", zap.String("status", string(stored.Status)))
+			return
+		}
+
+		done, err := r.runBatch(jobCtx, job, handler)
+		if runCtx.Err() != nil && !r.cancelled(entry) {
+			// 服务关闭：保留 running 状态，已完成的批次照常保存游标，下次启动时继续
+			if err == nil {
+				if err := r.store.UpdateMaintenanceJob(saveCtx, job); err != nil {
+					log.Error("failed to save maintenance job progress", zap.Error(err))
+				}
+			}
+			log.Info("maintenance job paused for shutdown", zap.String("cursor", job.Cursor))
+			return
+		}
+		switch {
+		case r.cancelled(entry):
+			r.finish(job, domain.MaintenanceJobCancelled, "")
+		case err != nil:
+			r.finish(job, domain.MaintenanceJobFailed, err.Error())
+		case done:
+			r.finish(job, domain.MaintenanceJobSucceeded, "")
+		}
+
+		if err := r.store.UpdateMaintenanceJob(saveCtx, job); err != nil {
+			// 游标没有保存：任务保持 running，下次启动时从上一个游标重新处理这一批
+			log.Error("failed to save maintenance job progress", zap.Error(err))
+			return
+		}
+		if !job.Active() {
+			log.Info("maintenance job finished",
+				zap.String("status", string(job.Status)),
+				zap.Int64("processed", job.Processed),
+				zap.Int64("updated", job.Updated),
+				zap.String("error", job.Error))
+			return
+		}
+	}
+}
+
+// runBatch 处理游标之后的一批记录，全部成功后推进游标和计数，返回是否已没有更多记录
+func (r *Runner) runBatch(ctx context.Context, job *domain.MaintenanceJob, handler Handler) (bool, error) {
+	items, err := handler.Next(ctx, job.Params, job.Cursor, r.batchSize)
+	if err != nil {
+		return false, err
+	}
+	if len(items) == 0 {
+		return true, nil
+	}
+
+	var (
+		mu      sync.Mutex
+		updated int64
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(r.concurrency)
+	for _, item := range items {
+		if r.limiter != nil {
+			if err := r.limiter.Wait(gctx); err != nil {
+				break // ctx 已取消，错误由 g.Wait 或调用方的 ctx 检查返回
+			}
+		}
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			changed, err := item.Apply(gctx)
+			if err != nil {
+				return err
+			}
+			if changed {
+				mu.Lock()
+				updated++
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return false, err
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	job.Cursor = items[len(items)-1].Key
+	job.Processed += int64(len(items))
+	job.Updated += updated
+	job.UpdatedAt = r.now()
+	return len(items) < r.batchSize, nil
+}
+
+// cancelled 任务是否被管理员取消
+func (r *Runner) cancelled(entry *runningJob) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return entry.cancelled
+}
+
+// finish 设置任务的结束状态
+func (r *Runner) finish(job *domain.MaintenanceJob, status domain.MaintenanceJobStatus, errMsg string) {
+	now := r.now()
+	job.Status = status
+	job.Error = errMsg
+	job.FinishedAt = &now
+	job.UpdatedAt = now
+}
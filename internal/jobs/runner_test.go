@@ -0,0 +1,266 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+const fakeJob domain.MaintenanceJobType = "fake"
+
+// fakeHandler 按顺序处理 k01..kNN，apply 为空时每条都算作修改
+type fakeHandler struct {
+	keys  []string
+	apply func(ctx context.Context, key string) (bool, error)
+
+	mu      sync.Mutex
+	applied map[string]int
+	cursors []string // 每次 Next 收到的游标
+}
+
+func newFakeHandler(n int) *fakeHandler {
+	h := &fakeHandler{applied: make(map[string]int)}
+	for i := 1; i <= n; i++ {
+		h.keys = append(h.keys, fmt.Sprintf("k%02d", i))
+	}
+	return h
+}
+
+func (h *fakeHandler) Total(ctx context.Context, params map[string]string) (int64, error) {
+	return int64(len(h.keys)), nil
+}
+
+func (h *fakeHandler) Next(ctx context.Context, params map[string]string, cursor string, limit int) ([]Item, error) {
+	h.mu.Lock()
+	h.cursors = append(h.cursors, cursor)
+	h.mu.Unlock()
+
+	var items []Item
+	for _, key := range h.keys {
+		if key <= cursor || len(items) == limit {
+			continue
+		}
+		items = append(items, Item{Key: key, Apply: func(ctx context.Context) (bool, error) {
+			changed := true
+			if h.apply != nil {
+				var err error
+				if changed, err = h.apply(ctx, key); err != nil {
+					return false, err
+				}
+			}
+			h.mu.Lock()
+			h.applied[key]++
+			h.mu.Unlock()
+			return changed, nil
+		}})
+	}
+	return items, nil
+}
+
+func (h *fakeHandler) appliedCount(key string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.applied[key]
+}
+
+func newTestRunner(store *memory.Store, handler Handler) *Runner {
+	runner := NewRunner(store, nil)
+	runner.Register(fakeJob, handler)
+	runner.SetBatchSize(3)
+	runner.SetConcurrency(2)
+	return runner
+}
+
+// startRunner 在后台运行调度器，返回停止函数（等待 Run 退出）
+func startRunner(t *testing.T, runner *Runner) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("runner did not stop")
+			}
+		})
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func waitStatus(t *testing.T, store *memory.Store, id string, status domain.MaintenanceJobStatus) *domain.MaintenanceJob {
+	t.Helper()
+	var job *domain.MaintenanceJob
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = store.GetMaintenanceJob(context.Background(), id)
+		return err == nil && job.Status == status
+	}, 5*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestRunner(t *testing.T) {
+	t.Run("分批处理并统计进度", func(t *testing.T) {
+		store := memory.NewStore(time.Hour)
+		handler := newFakeHandler(7)
+		handler.apply = func(ctx context.Context, key string) (bool, error) { return key != "k04", nil }
+		runner := newTestRunner(store, handler)
+		startRunner(t, runner)
+
+		job, err := runner.Enqueue(t.Context(), fakeJob, map[string]string{"scope": "all"}, "admin-1")
+		require.NoError(t, err)
+		assert.Equal(t, domain.MaintenanceJobQueued, job.Status)
+
+		job = waitStatus(t, store, job.ID, domain.MaintenanceJobSucceeded)
+		assert.Equal(t, int64(7), job.Total)
+		assert.Equal(t, int64(7), job.Processed)
+		assert.Equal(t, int64(6), job.Updated, "未修改的记录不计入")
+		assert.Equal(t, "k07", job.Cursor)
+		assert.NotNil(t, job.StartedAt)
+		assert.NotNil(t, job.FinishedAt)
+		assert.Equal(t, []string{"", "k03", "k06"}, handler.cursors, "最后一批不足一批时结束")
+		for _, key := range handler.keys {
+			assert.Equal(t, 1, handler.appliedCount(key), key)
+		}
+	})
+
+	t.Run("中途失败时停在最近的游标", func(t *testing.T) {
+		store := memory.NewStore(time.Hour)
+		handler := newFakeHandler(9)
+		handler.apply = func(ctx context.Context, key string) (bool, error) {
+			if key == "k05" {
+				return false, errors.New("disk full")
+			}
+			return true, nil
+		}
+		runner := newTestRunner(store, handler)
+		startRunner(t, runner)
+
+		job, err := runner.Enqueue(t.Context(), fakeJob, nil, "admin-1")
+		require.NoError(t, err)
+		job = waitStatus(t, store, job.ID, domain.MaintenanceJobFailed)
+		assert.Equal(t, "disk full", job.Error)
+		assert.Equal(t, "k03", job.Cursor, "失败的批次不推进游标")
+		assert.Equal(t, int64(3), job.Processed)
+		assert.Equal(t, int64(3), job.Updated)
+		assert.Zero(t, handler.appliedCount("k07"), "失败后不再处理后续批次")
+
+		_, err = runner.Enqueue(t.Context(), fakeJob, nil, "admin-1")
+		assert.NoError(t, err, "失败后释放同类型占用")
+	})
+
+	t.Run("重启后从游标继续", func(t *testing.T) {
+		store := memory.NewStore(time.Hour)
+		blocked := make(chan struct{})
+		first := newFakeHandler(8)
+		first.apply = func(ctx context.Context, key string) (bool, error) {
+			if key == "k05" {
+				close(blocked)
+				<-ctx.Done() // 第二批处理中服务关闭
+				return false, ctx.Err()
+			}
+			return true, nil
+		}
+		runner := newTestRunner(store, first)
+		stop := startRunner(t, runner)
+
+		job, err := runner.Enqueue(t.Context(), fakeJob, nil, "admin-1")
+		require.NoError(t, err)
+		<-blocked
+		stop()
+
+		paused, err := store.GetMaintenanceJob(t.Context(), job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.MaintenanceJobRunning, paused.Status, "关闭时不标记失败")
+		assert.Equal(t, "k03", paused.Cursor)
+		assert.Equal(t, int64(3), paused.Processed)
+
+		second := newFakeHandler(8)
+		startRunner(t, newTestRunner(store, second))
+		job = waitStatus(t, store, job.ID, domain.MaintenanceJobSucceeded)
+		assert.Equal(t, "k03", second.cursors[0], "从保存的游标开始")
+		assert.Zero(t, second.appliedCount("k01"), "已完成的批次不再处理")
+		assert.Equal(t, 1, second.appliedCount("k04"), "中断的批次重新处理")
+		assert.Equal(t, int64(8), job.Total)
+		assert.Equal(t, int64(8), job.Processed)
+		assert.Equal(t, int64(8), job.Updated)
+	})
+
+	t.Run("取消在当前批内停止", func(t *testing.T) {
+		store := memory.NewStore(time.Hour)
+		blocked := make(chan struct{})
+		handler := newFakeHandler(12)
+		handler.apply = func(ctx context.Context, key string) (bool, error) {
+			if key == "k04" {
+				close(blocked)
+				<-ctx.Done()
+				return false, ctx.Err()
+			}
+			return true, nil
+		}
+		runner := newTestRunner(store, handler)
+		startRunner(t, runner)
+
+		job, err := runner.Enqueue(t.Context(), fakeJob, nil, "admin-1")
+		require.NoError(t, err)
+		<-blocked
+		_, err = runner.Cancel(t.Context(), job.ID)
+		require.NoError(t, err)
+
+		job = waitStatus(t, store, job.ID, domain.MaintenanceJobCancelled)
+		assert.Equal(t, int64(3), job.Processed, "已完成的批次保留")
+		assert.Equal(t, "k03", job.Cursor)
+		assert.Len(t, handler.cursors, 2, "取消后不再列出下一批")
+		assert.Zero(t, handler.appliedCount("k07"))
+
+		_, err = runner.Cancel(t.Context(), job.ID)
+		assert.ErrorIs(t, err, ErrJobFinished)
+	})
+
+	t.Run("取消排队中的任务", func(t *testing.T) {
+		store := memory.NewStore(time.Hour)
+		runner := newTestRunner(store, newFakeHandler(3))
+
+		job, err := runner.Enqueue(t.Context(), fakeJob, nil, "admin-1")
+		require.NoError(t, err)
+		job, err = runner.Cancel(t.Context(), job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.MaintenanceJobCancelled, job.Status)
+
+		startRunner(t, runner)
+		_, err = runner.Enqueue(t.Context(), fakeJob, nil, "admin-1")
+		assert.NoError(t, err, "取消后释放同类型占用")
+	})
+
+	t.Run("同类型同时只能有一个任务", func(t *testing.T) {
+		store := memory.NewStore(time.Hour)
+		runner := newTestRunner(store, newFakeHandler(3))
+
+		_, err := runner.Enqueue(t.Context(), fakeJob, nil, "admin-1")
+		require.NoError(t, err)
+		_, err = runner.Enqueue(t.Context(), fakeJob, nil, "admin-2")
+		assert.ErrorIs(t, err, ErrJobActive)
+		_, err = runner.Enqueue(t.Context(), "unknown", nil, "admin-1")
+		assert.ErrorIs(t, err, ErrUnknownJobType)
+
+		jobs, err := runner.List(t.Context())
+		require.NoError(t, err)
+		assert.Len(t, jobs, 1)
+	})
+}
@@ -0,0 +1,256 @@
+// Package leader 协调多实例部署中的定时任务，保证每个周期只有一个实例执行
+//
+// 每次触发时以任务名获取锁，租期略短于任务周期：执行完成后锁保留到获取时算起的租期结束，
+// 其他实例在同一周期内的触发因此被跳过；持有锁的实例退出后锁同样在租期结束时过期，下个周期由其他实例接管。
+// 执行期间每隔三分之一租期续期一次，避免执行时间超过租期时其他实例重复执行；完成后把过期时间
+// 改回获取时的租期结束时间，执行较久也不会占用下一个周期。续期发现锁已被接管时取消任务的 context。
+// 未配置 Redis 时使用进程内锁，只在单个实例内互斥。
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ErrLockLost 任务执行期间锁已过期并被其他实例获取
+var ErrLockLost = errors.New("task lock lost")
+
+// keyPrefix 任务锁名前缀
+const keyPrefix = "task:"
+
+// releaseTimeout 关闭时释放锁的超时
+const releaseTimeout = 3 * time.Second
+
+// Locker 带租期的互斥锁（Redis 实现见 storage/redis.Locker）
+type Locker interface {
+	// Acquire 尝试获取锁，已被占用时返回 false
+	Acquire(ctx context.Context, name, token string, ttl time.Duration) (bool, error)
+	// Refresh 续期 token 持有的锁，锁已过期或被其他持有者占用时返回 false
+	Refresh(ctx context.Context, name, token string, ttl time.Duration) (bool, error)
+	// Release 释放 token 持有的锁
+	Release(ctx context.Context, name, token string) error
+}
+
+// Coordinator 定时任务协调器（各任务共用一个实例）
+type Coordinator struct {
+	locker Locker
+	owner  string
+	log    *zap.Logger
+
+	attempts *prometheus.CounterVec
+	lost     *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewCoordinator 创建协调器
+//
+// reg 为 nil 时使用默认注册表；locker 为 nil 时使用进程内锁。
+func NewCoordinator(reg prometheus.Registerer, locker Locker, log *zap.Logger) *Coordinator {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	if locker == nil {
+		locker = NewLocalLocker()
+	}
+	if log == nil {
+		log = zap.NewNop()
+	}
+	factory := promauto.With(reg)
+	return &Coordinator{
+		locker: locker,
+		owner:  ownerID(),
+		log:    log,
+		attempts: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tempmail_task_lock_attempts_total",
+				Help: "Periodic task lock acquisition attempts by result (acquired, skipped, error)",
+			},
+			[]string{"task", "result"},
+		),
+		lost: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tempmail_task_lock_lost_total",
+				Help: "Periodic task runs cancelled because the lock was taken over",
+			},
+			[]string{"task"},
+		),
+		duration: factory.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "tempmail_task_duration_seconds",
+				Help:    "Periodic task run duration in seconds",
+				Buckets: prometheus.ExponentialBuckets(0.01, 2, 14), // 10ms ~ 80s
+			},
+			[]string{"task", "status"},
+		),
+	}
+}
+
+// Owner 本实例的锁持有者标识（主机名加随机后缀）
+func (c *Coordinator) Owner() string {
+	return c.owner
+}
+
+// Run 获取到本周期的锁时执行 task，返回是否执行
+//
+// interval 为任务周期，锁的租期为周期的 90%。锁被其他实例持有时跳过；获取锁出错时同样跳过，
+// 宁可少执行一次也不在多个实例上重复执行。task 的错误原样返回，锁丢失时返回的错误包含 ErrLockLost。
+func (c *Coordinator) Run(ctx context.Context, name string, interval time.Duration, task func(ctx context.Context) error) (bool, error) {
+	key := keyPrefix + name
+	lease := max(interval-interval/10, time.Second)
+
+	ok, err := c.locker.Acquire(ctx, key, c.owner, lease)
+	if err != nil {
+		c.attempts.WithLabelValues(name, "error").Inc()
+		return false, fmt.Errorf("acquire lock for task %s: %w", name, err)
+	}
+	if !ok {
+		c.attempts.WithLabelValues(name, "skipped").Inc()
+		return false, nil
+	}
+	c.attempts.WithLabelValues(name, "acquired").Inc()
+	start := time.Now()
+
+	taskCtx, cancel := context.WithCancelCause(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.keepAlive(taskCtx, name, key, lease, cancel)
+	}()
+
+	err = task(taskCtx)
+	lockLost := errors.Is(context.Cause(taskCtx), ErrLockLost)
+	cancel(nil)
+	wg.Wait()
+
+	status := "ok"
+	if err != nil || lockLost {
+		status = "error"
+	}
+	c.duration.WithLabelValues(name, status).Observe(time.Since(start).Seconds())
+
+	if lockLost {
+		c.lost.WithLabelValues(name).Inc()
+		return true, errors.Join(ErrLockLost, err)
+	}
+
+	c.settle(ctx, name, key, time.Until(start.Add(lease)))
+	return true, err
+}
+
+// settle 执行结束后处理锁：服务关闭或租期已过时释放，其他实例不必等待即可接管；
+// 否则把过期时间改回获取时的租期结束时间（执行期间的续期可能已推迟过期时间）
+func (c *Coordinator) settle(ctx context.Context, name, key string, remaining time.Duration) {
+	lockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+
+	if ctx.Err() != nil || remaining < time.Millisecond {
+		if err := c.locker.Release(lockCtx, key, c.owner); err != nil {
+			c.log.Warn("failed to release task lock", zap.String("task", name), zap.Error(err))
+		}
+		return
+	}
+	if _, err := c.locker.Refresh(lockCtx, key, c.owner, remaining); err != nil {
+		c.log.Warn("failed to restore task lock lease", zap.String("task", name), zap.Error(err))
+	}
+}
+
+// keepAlive 任务执行期间定期续期，锁已被接管时以 ErrLockLost 取消任务
+func (c *Coordinator) keepAlive(ctx context.Context, name, key string, lease time.Duration, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := c.locker.Refresh(ctx, key, c.owner, lease)
+			if err != nil {
+				// 暂时性错误不中断任务，锁在租期内仍然有效
+				if ctx.Err() == nil {
+					c.log.Warn("failed to refresh task lock", zap.String("task", name), zap.Error(err))
+				}
+				continue
+			}
+			if !ok {
+				c.log.Warn("task lock lost, cancelling run", zap.String("task", name))
+				cancel(ErrLockLost)
+				return
+			}
+		}
+	}
+}
+
+// ownerID 生成本实例的持有者标识
+func ownerID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return host + "-" + uuid.NewString()[:8]
+}
+
+// LocalLocker 进程内的锁，单实例部署或未配置 Redis 时使用
+type LocalLocker struct {
+	mu    sync.Mutex
+	locks map[string]localLock
+	now   func() time.Time
+}
+
+type localLock struct {
+	token     string
+	expiresAt time.Time
+}
+
+// NewLocalLocker 创建进程内锁
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{locks: make(map[string]localLock), now: time.Now}
+}
+
+// Acquire 尝试获取锁，已被占用且未过期时返回 false
+func (l *LocalLocker) Acquire(_ context.Context, name, token string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if lock, ok := l.locks[name]; ok && now.Before(lock.expiresAt) {
+		return false, nil
+	}
+	l.locks[name] = localLock{token: token, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// Refresh 续期 token 持有的锁
+func (l *LocalLocker) Refresh(_ context.Context, name, token string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	lock, ok := l.locks[name]
+	if !ok || lock.token != token || !now.Before(lock.expiresAt) {
+		return false, nil
+	}
+	l.locks[name] = localLock{token: token, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// Release 释放 token 持有的锁
+func (l *LocalLocker) Release(_ context.Context, name, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lock, ok := l.locks[name]; ok && lock.token == token {
+		delete(l.locks, name)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestCoordinator_RunOncePerInterval(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	locker := NewLocalLocker()
+	locker.now = clock.Now
+
+	reg := prometheus.NewRegistry()
+	a := NewCoordinator(reg, locker, nil)
+	b := NewCoordinator(prometheus.NewRegistry(), locker, nil)
+
+	runs := 0
+	task := func(context.Context) error { runs++; return nil }
+	ctx := context.Background()
+
+	ran, err := a.Run(ctx, "cleanup", time.Hour, task)
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	// 同一周期内其他实例（和本实例）的触发被跳过
+	clock.now = clock.now.Add(30 * time.Second)
+	ran, err = b.Run(ctx, "cleanup", time.Hour, task)
+	require.NoError(t, err)
+	assert.False(t, ran)
+	ran, err = a.Run(ctx, "cleanup", time.Hour, task)
+	require.NoError(t, err)
+	assert.False(t, ran)
+
+	// 不同任务互不影响
+	ran, err = b.Run(ctx, "webhook_retry", time.Hour, task)
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	// 租期结束后下一个周期由任意实例执行
+	clock.now = clock.now.Add(time.Hour)
+	ran, err = b.Run(ctx, "cleanup", time.Hour, task)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, 3, runs)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(a.attempts.WithLabelValues("cleanup", "acquired")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(a.attempts.WithLabelValues("cleanup", "skipped")))
+	assert.Equal(t, 1, testutil.CollectAndCount(a.duration, "tempmail_task_duration_seconds"))
+}
+
+func TestCoordinator_SlowTaskKeepsNextInterval(t *testing.T) {
+	c := NewCoordinator(prometheus.NewRegistry(), nil, nil)
+	ctx := context.Background()
+	interval := 1500 * time.Millisecond // 租期 1.35 秒，每 450ms 续期
+
+	start := time.Now()
+	ran, err := c.Run(ctx, "cleanup", interval, func(context.Context) error {
+		time.Sleep(700 * time.Millisecond) // 超过租期的三分之一，期间至少续期一次
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	// 续期推迟的过期时间在完成后恢复，锁在获取时算起的租期结束时过期，下一个周期照常执行
+	time.Sleep(time.Until(start.Add(interval)))
+	ran, err = c.Run(ctx, "cleanup", interval, func(context.Context) error { return nil })
+	require.NoError(t, err)
+	assert.True(t, ran)
+}
+
+func TestCoordinator_TaskError(t *testing.T) {
+	c := NewCoordinator(prometheus.NewRegistry(), nil, nil)
+	boom := errors.New("boom")
+
+	ran, err := c.Run(context.Background(), "cleanup", time.Hour, func(context.Context) error { return boom })
+	assert.True(t, ran)
+	assert.ErrorIs(t, err, boom)
+
+	count := testutil.CollectAndCount(c.duration, "tempmail_task_duration_seconds")
+	assert.Equal(t, 1, count)
+}
+
+func TestCoordinator_ReleaseOnShutdown(t *testing.T) {
+	locker := NewLocalLocker()
+	c := NewCoordinator(prometheus.NewRegistry(), locker, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran, err := c.Run(ctx, "cleanup", time.Hour, func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	assert.True(t, ran)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// 关闭时释放了锁，其他实例可以立即接管
+	ok, err := locker.Acquire(context.Background(), keyPrefix+"cleanup", "other", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+// stolenLocker 续期总是失败，模拟锁被其他实例接管
+type stolenLocker struct{ *LocalLocker }
+
+func (stolenLocker) Refresh(context.Context, string, string, time.Duration) (bool, error) {
+	return false, nil
+}
+
+func TestCoordinator_LockLost(t *testing.T) {
+	c := NewCoordinator(prometheus.NewRegistry(), stolenLocker{NewLocalLocker()}, nil)
+
+	// 1.1 秒周期的租期为 1 秒，约 333ms 后续期失败并取消任务
+	ran, err := c.Run(context.Background(), "cleanup", 1100*time.Millisecond, func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	})
+	assert.True(t, ran)
+	assert.ErrorIs(t, err, ErrLockLost)
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.lost.WithLabelValues("cleanup")))
+}
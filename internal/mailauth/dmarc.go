@@ -0,0 +1,120 @@
+package mailauth
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"tempmail/backend/internal/domain"
+)
+
+// dmarcRecord 解析后的 DMARC 记录（只取评估需要的标签）
+type dmarcRecord struct {
+	policy          string // p=
+	subdomainPolicy string // sp=，缺省同 p=
+	strictDKIM      bool   // adkim=s
+	strictSPF       bool   // aspf=s
+}
+
+// lookupDMARC 查询 From 域名的 DMARC 记录，没有时回退到组织域名（RFC 7489 6.6.3）
+//
+// 返回的策略已按 From 域名是否为子域名选好 p= 或 sp=；没有记录时 record 为 nil，
+// DNS 临时错误时 result 为 temperror。
+func lookupDMARC(ctx context.Context, resolver Resolver, fromDomain string) (*dmarcRecord, string, string) {
+	record, result := fetchDMARC(ctx, resolver, fromDomain)
+	if record != nil || result != "" {
+		if record != nil {
+			return record, record.policy, ""
+		}
+		return nil, "", result
+	}
+	org := organizationalDomain(fromDomain)
+	if org == fromDomain {
+		return nil, "", ""
+	}
+	record, result = fetchDMARC(ctx, resolver, org)
+	if record == nil {
+		return nil, "", result
+	}
+	return record, record.subdomainPolicy, ""
+}
+
+// fetchDMARC 查询 _dmarc.<name>；没有有效记录时返回 nil 和空结果
+func fetchDMARC(ctx context.Context, resolver Resolver, name string) (*dmarcRecord, string) {
+	records, err := resolver.LookupTXT(ctx, "_dmarc."+name)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ""
+		}
+		return nil, domain.DMARCResultTempError
+	}
+	var found *dmarcRecord
+	for _, text := range records {
+		record, ok := parseDMARC(text)
+		if !ok {
+			continue
+		}
+		if found != nil {
+			return nil, "" // 多条记录视为没有记录
+		}
+		found = record
+	}
+	return found, ""
+}
+
+// parseDMARC 解析 "v=DMARC1; p=reject; ..." 格式的记录
+func parseDMARC(text string) (*dmarcRecord, bool) {
+	tags := strings.Split(text, ";")
+	if !strings.EqualFold(strings.ReplaceAll(strings.TrimSpace(tags[0]), " ", ""), "v=DMARC1") {
+		return nil, false
+	}
+	record := &dmarcRecord{}
+	for _, tag := range tags[1:] {
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok {
+			continue
+		}
+		value = strings.ToLower(strings.TrimSpace(value))
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "p":
+			record.policy = value
+		case "sp":
+			record.subdomainPolicy = value
+		case "adkim":
+			record.strictDKIM = value == "s"
+		case "aspf":
+			record.strictSPF = value == "s"
+		}
+	}
+	if !validPolicy(record.policy) {
+		// p= 缺失或无效时按 none 处理（RFC 7489 6.6.3 第 6 步）
+		record.policy = "none"
+	}
+	if !validPolicy(record.subdomainPolicy) {
+		record.subdomainPolicy = record.policy
+	}
+	return record, true
+}
+
+func validPolicy(policy string) bool {
+	return policy == "none" || policy == "quarantine" || policy == "reject"
+}
+
+// aligned 认证通过的域名与 From 域名是否对齐：严格模式要求完全相同，宽松模式要求组织域名相同
+func aligned(authDomain, fromDomain string, strict bool) bool {
+	authDomain = strings.ToLower(strings.TrimSuffix(authDomain, "."))
+	if authDomain == fromDomain {
+		return true
+	}
+	return !strict && authDomain != "" && organizationalDomain(authDomain) == organizationalDomain(fromDomain)
+}
+
+// organizationalDomain 组织域名（公共后缀再加一级），无法判断时返回原域名
+func organizationalDomain(name string) string {
+	org, err := publicsuffix.EffectiveTLDPlusOne(name)
+	if err != nil {
+		return name
+	}
+	return org
+}
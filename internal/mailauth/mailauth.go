@@ -0,0 +1,96 @@
+// Package mailauth 收信时的发件人认证：SPF（RFC 7208）与 DMARC（RFC 7489）
+//
+// DKIM 签名由 internal/dkim 验证，这里只使用其结果做 DMARC 对齐检查。
+package mailauth
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/dkim"
+	"tempmail/backend/internal/domain"
+)
+
+// defaultTimeout 一封邮件全部 DNS 查询的超时
+const defaultTimeout = 10 * time.Second
+
+// Resolver 认证需要的 DNS 查询，*net.Resolver 满足该接口
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// Input 一封邮件的认证输入
+type Input struct {
+	IP         net.IP // 连接 IP
+	Helo       string // HELO/EHLO 名
+	MailFrom   string // 信封发件人（MAIL FROM），退信时为空
+	HeaderFrom string // From 头的域名
+	DKIM       dkim.Verification
+}
+
+// Authenticator 发件人认证，可并发使用
+type Authenticator struct {
+	resolver Resolver
+	action   string
+	timeout  time.Duration
+}
+
+// NewAuthenticator 创建认证器；resolver 为 nil 时使用系统 DNS，action 为认证未通过时的处理方式
+func NewAuthenticator(resolver Resolver, action string) *Authenticator {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	switch action {
+	case domain.AuthActionQuarantine, domain.AuthActionReject:
+	default:
+		action = domain.AuthActionAccept
+	}
+	return &Authenticator{resolver: resolver, action: action, timeout: defaultTimeout}
+}
+
+// Evaluate 检查 SPF 和 DMARC，未通过时在结果中记录处理方式
+func (a *Authenticator) Evaluate(ctx context.Context, in Input) *domain.AuthenticationResults {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	results := &domain.AuthenticationResults{
+		DKIM:       in.DKIM.Result,
+		DKIMDomain: in.DKIM.Domain,
+		HeaderFrom: strings.ToLower(strings.TrimSuffix(in.HeaderFrom, ".")),
+		DMARC:      domain.DMARCResultNone,
+	}
+	results.SPF, results.SPFDomain = CheckSPF(ctx, a.resolver, in.IP, in.Helo, in.MailFrom)
+
+	if validDomain(results.HeaderFrom) {
+		record, policy, result := lookupDMARC(ctx, a.resolver, results.HeaderFrom)
+		switch {
+		case result != "":
+			results.DMARC = result
+		case record != nil:
+			results.DMARCPolicy = policy
+			results.DMARC = domain.DMARCResultFail
+			if dmarcPass(record, results, in.DKIM.Passed) {
+				results.DMARC = domain.DMARCResultPass
+			}
+		}
+	}
+
+	if results.Failed() {
+		results.Action = a.action
+	}
+	return results
+}
+
+// dmarcPass 任一通过的 DKIM 签名域名或通过的 SPF 域名与 From 域名对齐即通过
+func dmarcPass(record *dmarcRecord, results *domain.AuthenticationResults, dkimPassed []string) bool {
+	for _, d := range dkimPassed {
+		if aligned(d, results.HeaderFrom, record.strictDKIM) {
+			return true
+		}
+	}
+	return results.SPF == domain.SPFResultPass && aligned(results.SPFDomain, results.HeaderFrom, record.strictSPF)
+}
@@ -0,0 +1,201 @@
+package mailauth
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"tempmail/backend/internal/dkim"
+	"tempmail/backend/internal/domain"
+)
+
+// fakeResolver 固定记录的 DNS，未配置的名字返回 NXDOMAIN，temp 中的名字返回超时
+type fakeResolver struct {
+	txt  map[string][]string
+	ip   map[string][]string
+	mx   map[string][]string
+	temp map[string]bool
+}
+
+func (r *fakeResolver) fail(name string) error {
+	if r.temp[name] {
+		return &net.DNSError{Err: "timeout", Name: name, IsTimeout: true}
+	}
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if records, ok := r.txt[name]; ok {
+		return records, nil
+	}
+	return nil, r.fail(name)
+}
+
+func (r *fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ok := r.ip[host]
+	if !ok {
+		return nil, r.fail(host)
+	}
+	var out []net.IPAddr
+	for _, addr := range addrs {
+		out = append(out, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+	return out, nil
+}
+
+func (r *fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	hosts, ok := r.mx[name]
+	if !ok {
+		return nil, r.fail(name)
+	}
+	var out []*net.MX
+	for _, host := range hosts {
+		out = append(out, &net.MX{Host: host, Pref: 10})
+	}
+	return out, nil
+}
+
+func TestCheckSPF(t *testing.T) {
+	resolver := &fakeResolver{
+		txt: map[string][]string{
+			"example.com":        {"v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::/32 a:web.example.com mx include:_spf.example.com -all"},
+			"_spf.example.com":   {"v=spf1 ip4:203.0.113.7 ~all"},
+			"soft.example":       {"v=spf1 ~all"},
+			"neutral.example":    {"some other record", "v=spf1 ?all"},
+			"redirect.example":   {"v=spf1 redirect=example.com"},
+			"macro.example":      {"v=spf1 exists:%{ir}.%{l1r+-}._spf.%{d} -all"},
+			"loop.example":       {"v=spf1 include:loop.example -all"},
+			"double.example":     {"v=spf1 -all", "v=spf1 +all"},
+			"badinclude.example": {"v=spf1 include:missing.example -all"},
+			"temp.example":       {"v=spf1 a:slow.example -all"},
+			"voids.example":      {"v=spf1 a:v1.example a:v2.example a:v3.example -all"},
+		},
+		ip: map[string][]string{
+			"web.example.com":                   {"198.51.100.10"},
+			"mx1.example.com.":                  {"198.51.100.25"},
+			"1.2.0.192.user._spf.macro.example": {"127.0.0.2"},
+		},
+		mx:   map[string][]string{"example.com": {"mx1.example.com."}},
+		temp: map[string]bool{"slow.example": true},
+	}
+	check := func(ip, mailFrom string) string {
+		result, _ := CheckSPF(t.Context(), resolver, net.ParseIP(ip), "mail.sender.test", mailFrom)
+		return result
+	}
+
+	t.Run("机制匹配", func(t *testing.T) {
+		assert.Equal(t, domain.SPFResultPass, check("192.0.2.55", "alice@example.com"))
+		assert.Equal(t, domain.SPFResultPass, check("2001:db8::1", "alice@example.com"))
+		assert.Equal(t, domain.SPFResultPass, check("198.51.100.10", "alice@example.com"))
+		assert.Equal(t, domain.SPFResultPass, check("198.51.100.25", "alice@example.com"))
+		assert.Equal(t, domain.SPFResultPass, check("203.0.113.7", "<alice@EXAMPLE.com>"))
+		assert.Equal(t, domain.SPFResultFail, check("203.0.113.8", "alice@example.com"))
+	})
+
+	t.Run("限定符与无记录", func(t *testing.T) {
+		assert.Equal(t, domain.SPFResultSoftFail, check("192.0.2.1", "a@soft.example"))
+		assert.Equal(t, domain.SPFResultNeutral, check("192.0.2.1", "a@neutral.example"))
+		assert.Equal(t, domain.SPFResultNone, check("192.0.2.1", "a@nospf.example"))
+	})
+
+	t.Run("redirect 与宏", func(t *testing.T) {
+		assert.Equal(t, domain.SPFResultPass, check("192.0.2.1", "a@redirect.example"))
+		assert.Equal(t, domain.SPFResultFail, check("10.0.0.1", "a@redirect.example"))
+		assert.Equal(t, domain.SPFResultPass, check("192.0.2.1", "user-macro@macro.example"))
+		assert.Equal(t, domain.SPFResultFail, check("192.0.2.2", "user-macro@macro.example"))
+	})
+
+	t.Run("错误", func(t *testing.T) {
+		assert.Equal(t, domain.SPFResultPermError, check("192.0.2.1", "a@loop.example"))
+		assert.Equal(t, domain.SPFResultPermError, check("192.0.2.1", "a@double.example"))
+		assert.Equal(t, domain.SPFResultPermError, check("192.0.2.1", "a@badinclude.example"))
+		assert.Equal(t, domain.SPFResultTempError, check("192.0.2.1", "a@temp.example"))
+		assert.Equal(t, domain.SPFResultPermError, check("192.0.2.1", "a@voids.example"))
+	})
+
+	t.Run("空发件人检查 HELO", func(t *testing.T) {
+		result, checked := CheckSPF(t.Context(), resolver, net.ParseIP("192.0.2.1"), "example.com", "")
+		assert.Equal(t, domain.SPFResultPass, result)
+		assert.Equal(t, "example.com", checked)
+	})
+}
+
+func TestAuthenticator_Evaluate(t *testing.T) {
+	resolver := &fakeResolver{
+		txt: map[string][]string{
+			"example.com":           {"v=spf1 ip4:192.0.2.0/24 -all"},
+			"bounce.example.com":    {"v=spf1 ip4:192.0.2.0/24 -all"},
+			"_dmarc.example.com":    {"v=DMARC1; p=reject; sp=quarantine"},
+			"strict.example":        {"v=spf1 ip4:192.0.2.0/24 -all"},
+			"mail.strict.example":   {"v=spf1 ip4:192.0.2.0/24 -all"},
+			"_dmarc.strict.example": {"v=DMARC1; p=quarantine; aspf=s; adkim=s"},
+			"nodmarc.example":       {"v=spf1 -all"},
+		},
+		temp: map[string]bool{"_dmarc.slow.example": true},
+	}
+	in := func(ip, mailFrom, from string, verification dkim.Verification) Input {
+		return Input{IP: net.ParseIP(ip), Helo: "mx.sender.test", MailFrom: mailFrom, HeaderFrom: from, DKIM: verification}
+	}
+
+	t.Run("SPF 对齐通过", func(t *testing.T) {
+		a := NewAuthenticator(resolver, domain.AuthActionReject)
+		results := a.Evaluate(t.Context(), in("192.0.2.1", "bounce@bounce.example.com", "example.com", dkim.Verification{Result: dkim.ResultNone}))
+		assert.Equal(t, &domain.AuthenticationResults{
+			SPF: domain.SPFResultPass, SPFDomain: "bounce.example.com", DKIM: dkim.ResultNone,
+			DMARC: domain.DMARCResultPass, DMARCPolicy: "reject", HeaderFrom: "example.com",
+		}, results)
+		assert.False(t, results.Failed())
+	})
+
+	t.Run("DKIM 对齐通过", func(t *testing.T) {
+		a := NewAuthenticator(resolver, domain.AuthActionReject)
+		results := a.Evaluate(t.Context(), in("10.0.0.1", "x@other.test", "example.com",
+			dkim.Verification{Result: dkim.ResultPass, Domain: "other.test", Passed: []string{"other.test", "news.example.com"}}))
+		assert.Equal(t, domain.DMARCResultPass, results.DMARC)
+		assert.Empty(t, results.Action)
+	})
+
+	t.Run("未对齐时失败并记录处理方式", func(t *testing.T) {
+		a := NewAuthenticator(resolver, domain.AuthActionQuarantine)
+		// SPF 通过但域名不对齐，DKIM 通过但域名不对齐
+		results := a.Evaluate(t.Context(), in("192.0.2.1", "x@strict.example", "example.com",
+			dkim.Verification{Result: dkim.ResultPass, Domain: "other.test", Passed: []string{"other.test"}}))
+		assert.Equal(t, domain.DMARCResultFail, results.DMARC)
+		assert.Equal(t, domain.AuthActionQuarantine, results.Action)
+		assert.True(t, results.Failed())
+	})
+
+	t.Run("严格对齐与子域名策略", func(t *testing.T) {
+		a := NewAuthenticator(resolver, domain.AuthActionAccept)
+		results := a.Evaluate(t.Context(), in("192.0.2.1", "x@mail.strict.example", "strict.example", dkim.Verification{}))
+		assert.Equal(t, domain.DMARCResultFail, results.DMARC)
+		assert.Equal(t, "quarantine", results.DMARCPolicy)
+		assert.Equal(t, domain.AuthActionAccept, results.Action)
+
+		// 子域名没有自己的记录时使用组织域名的 sp=
+		results = a.Evaluate(t.Context(), in("192.0.2.1", "x@example.com", "news.example.com", dkim.Verification{}))
+		assert.Equal(t, domain.DMARCResultPass, results.DMARC)
+		assert.Equal(t, "quarantine", results.DMARCPolicy)
+	})
+
+	t.Run("没有 DMARC 记录时看 SPF", func(t *testing.T) {
+		a := NewAuthenticator(resolver, "bogus")
+		results := a.Evaluate(t.Context(), in("192.0.2.1", "x@nodmarc.example", "nodmarc.example", dkim.Verification{}))
+		assert.Equal(t, domain.DMARCResultNone, results.DMARC)
+		assert.Equal(t, domain.SPFResultFail, results.SPF)
+		assert.Equal(t, domain.AuthActionAccept, results.Action)
+
+		results = a.Evaluate(t.Context(), in("192.0.2.1", "x@example.com", "unknown.test", dkim.Verification{}))
+		assert.Equal(t, domain.DMARCResultNone, results.DMARC)
+		assert.False(t, results.Failed())
+	})
+
+	t.Run("DNS 临时错误", func(t *testing.T) {
+		a := NewAuthenticator(resolver, domain.AuthActionReject)
+		results := a.Evaluate(t.Context(), in("192.0.2.1", "x@example.com", "slow.example", dkim.Verification{}))
+		assert.Equal(t, domain.DMARCResultTempError, results.DMARC)
+		assert.False(t, results.Failed())
+	})
+}
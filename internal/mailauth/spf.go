@@ -0,0 +1,476 @@
+package mailauth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"tempmail/backend/internal/domain"
+)
+
+const (
+	// maxSPFLookups 一次检查中触发 DNS 查询的机制和 redirect 的总数上限（RFC 7208 4.6.4）
+	maxSPFLookups = 10
+	// maxVoidLookups 没有结果的 DNS 查询上限
+	maxVoidLookups = 2
+	// maxMXHosts 每个 mx 机制最多检查的邮件服务器数
+	maxMXHosts = 10
+)
+
+// spfCheck 一次 SPF 检查的状态（跨 include/redirect 累计查询次数）
+type spfCheck struct {
+	resolver Resolver
+	ip       net.IP
+	sender   string // MAIL FROM（空发件人时为 postmaster@<HELO>）
+	helo     string
+	lookups  int
+	voids    int
+}
+
+// CheckSPF 检查连接 IP 是否被 MAIL FROM 的域名授权发信，返回结果和检查的域名
+//
+// MAIL FROM 为空（退信）时检查 HELO 名。
+func CheckSPF(ctx context.Context, resolver Resolver, ip net.IP, helo, mailFrom string) (string, string) {
+	sender := strings.Trim(strings.TrimSpace(mailFrom), "<>")
+	if sender == "" {
+		sender = "postmaster@" + helo
+	}
+	local, senderDomain, ok := strings.Cut(sender, "@")
+	switch {
+	case !ok:
+		senderDomain, sender = sender, "postmaster@"+sender
+	case local == "":
+		sender = "postmaster" + sender
+	}
+	senderDomain = strings.ToLower(strings.TrimSuffix(senderDomain, "."))
+	if ip == nil || !validDomain(senderDomain) {
+		return domain.SPFResultNone, senderDomain
+	}
+
+	check := &spfCheck{resolver: resolver, ip: ip, sender: sender, helo: helo}
+	return check.checkHost(ctx, senderDomain), senderDomain
+}
+
+// checkHost RFC 7208 的 check_host()
+func (c *spfCheck) checkHost(ctx context.Context, name string) string {
+	record, result := c.lookupRecord(ctx, name)
+	if record == "" {
+		return result
+	}
+
+	var redirect string
+	for _, term := range strings.Fields(record)[1:] {
+		if key, value, ok := strings.Cut(term, "="); ok && isModifierName(key) {
+			if strings.EqualFold(key, "redirect") {
+				if redirect != "" {
+					return domain.SPFResultPermError
+				}
+				redirect = value
+			}
+			continue // exp 和未知修饰符忽略
+		}
+
+		qualifier := domain.SPFResultPass
+		switch term[0] {
+		case '+':
+			term = term[1:]
+		case '-':
+			qualifier, term = domain.SPFResultFail, term[1:]
+		case '~':
+			qualifier, term = domain.SPFResultSoftFail, term[1:]
+		case '?':
+			qualifier, term = domain.SPFResultNeutral, term[1:]
+		}
+		matched, result := c.mechanism(ctx, name, term)
+		if result != "" {
+			return result
+		}
+		if matched {
+			return qualifier
+		}
+	}
+
+	if redirect == "" {
+		return domain.SPFResultNeutral
+	}
+	if c.lookups++; c.lookups > maxSPFLookups {
+		return domain.SPFResultPermError
+	}
+	target, err := c.expand(redirect, name)
+	if err != nil || !validDomain(target) {
+		return domain.SPFResultPermError
+	}
+	result = c.checkHost(ctx, target)
+	if result == domain.SPFResultNone {
+		return domain.SPFResultPermError
+	}
+	return result
+}
+
+// lookupRecord 查询域名的 SPF 记录，没有记录或出错时返回空记录和对应结果
+func (c *spfCheck) lookupRecord(ctx context.Context, name string) (string, string) {
+	records, err := c.resolver.LookupTXT(ctx, name)
+	if err != nil {
+		if isNotFound(err) {
+			return "", domain.SPFResultNone
+		}
+		return "", domain.SPFResultTempError
+	}
+	var found []string
+	for _, record := range records {
+		if lower := strings.ToLower(record); lower == "v=spf1" || strings.HasPrefix(lower, "v=spf1 ") {
+			found = append(found, record)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", domain.SPFResultNone
+	case 1:
+		return found[0], ""
+	}
+	return "", domain.SPFResultPermError
+}
+
+// mechanism 判断一个机制是否匹配；result 非空时检查立即以该结果结束
+func (c *spfCheck) mechanism(ctx context.Context, current, term string) (matched bool, result string) {
+	name, arg, hasArg := strings.Cut(term, ":")
+	if !hasArg {
+		// a/24、mx//64 这类省略域名只带前缀长度的写法
+		if i := strings.IndexByte(term, '/'); i >= 0 {
+			name, arg = term[:i], term[i:]
+		}
+	}
+	name = strings.ToLower(name)
+
+	switch name {
+	case "all":
+		return true, ""
+	case "ip4", "ip6":
+		return matchIPNetwork(c.ip, arg, name == "ip6")
+	}
+
+	if c.lookups++; c.lookups > maxSPFLookups {
+		return false, domain.SPFResultPermError
+	}
+	switch name {
+	case "include":
+		target, err := c.expand(arg, current)
+		if err != nil || !validDomain(target) {
+			return false, domain.SPFResultPermError
+		}
+		switch result := c.checkHost(ctx, target); result {
+		case domain.SPFResultPass:
+			return true, ""
+		case domain.SPFResultTempError:
+			return false, result
+		case domain.SPFResultPermError, domain.SPFResultNone:
+			return false, domain.SPFResultPermError
+		}
+		return false, ""
+	case "a", "mx":
+		spec, ip4Bits, ip6Bits, ok := splitCIDR(arg)
+		if !ok {
+			return false, domain.SPFResultPermError
+		}
+		target, err := c.targetDomain(spec, current)
+		if err != nil {
+			return false, domain.SPFResultPermError
+		}
+		hosts := []string{target}
+		if name == "mx" {
+			mxs, err := c.resolver.LookupMX(ctx, target)
+			if result := c.lookupFailure(err, len(mxs)); result != "" {
+				return false, result
+			}
+			hosts = hosts[:0]
+			for i, mx := range mxs {
+				if i >= maxMXHosts {
+					return false, domain.SPFResultPermError
+				}
+				hosts = append(hosts, mx.Host)
+			}
+		}
+		for _, host := range hosts {
+			addrs, err := c.resolver.LookupIPAddr(ctx, host)
+			if result := c.lookupFailure(err, len(addrs)); result != "" {
+				return false, result
+			}
+			for _, addr := range addrs {
+				if ipInRange(c.ip, addr.IP, ip4Bits, ip6Bits) {
+					return true, ""
+				}
+			}
+		}
+		return false, ""
+	case "exists":
+		target, err := c.expand(arg, current)
+		if err != nil || !validDomain(target) {
+			return false, domain.SPFResultPermError
+		}
+		addrs, err := c.resolver.LookupIPAddr(ctx, target)
+		if result := c.lookupFailure(err, len(addrs)); result != "" {
+			return false, result
+		}
+		for _, addr := range addrs {
+			if addr.IP.To4() != nil {
+				return true, ""
+			}
+		}
+		return false, ""
+	case "ptr":
+		// 不推荐使用（RFC 7208 5.5），按不匹配处理，只计入查询次数
+		return false, ""
+	}
+	return false, domain.SPFResultPermError
+}
+
+// lookupFailure 统计无结果的查询；出错时返回应当结束检查的结果
+func (c *spfCheck) lookupFailure(err error, count int) string {
+	if err != nil && !isNotFound(err) {
+		return domain.SPFResultTempError
+	}
+	if err != nil || count == 0 {
+		if c.voids++; c.voids > maxVoidLookups {
+			return domain.SPFResultPermError
+		}
+	}
+	return ""
+}
+
+// targetDomain 机制的目标域名（省略时为当前域名）
+func (c *spfCheck) targetDomain(spec, current string) (string, error) {
+	if spec == "" {
+		return current, nil
+	}
+	target, err := c.expand(spec, current)
+	if err != nil || !validDomain(target) {
+		return "", errors.New("spf: invalid domain-spec")
+	}
+	return target, nil
+}
+
+// expand 展开 domain-spec 中的宏（RFC 7208 7）
+func (c *spfCheck) expand(spec, current string) (string, error) {
+	if !strings.Contains(spec, "%") {
+		return strings.ToLower(strings.TrimSuffix(spec, ".")), nil
+	}
+	local, senderDomain, _ := strings.Cut(c.sender, "@")
+	var b strings.Builder
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != '%' {
+			b.WriteByte(spec[i])
+			continue
+		}
+		if i+1 >= len(spec) {
+			return "", errors.New("spf: dangling %")
+		}
+		i++
+		switch spec[i] {
+		case '%':
+			b.WriteByte('%')
+			continue
+		case '_':
+			b.WriteByte(' ')
+			continue
+		case '-':
+			b.WriteString("%20")
+			continue
+		case '{':
+		default:
+			return "", errors.New("spf: invalid macro")
+		}
+		end := strings.IndexByte(spec[i:], '}')
+		if end < 2 {
+			return "", errors.New("spf: invalid macro")
+		}
+		macro := spec[i+1 : i+end]
+		i += end
+
+		var value string
+		switch macro[0] | 0x20 {
+		case 's':
+			value = c.sender
+		case 'l':
+			value = local
+		case 'o':
+			value = senderDomain
+		case 'd':
+			value = current
+		case 'i':
+			value = macroIP(c.ip)
+		case 'p':
+			value = "unknown"
+		case 'v':
+			value = "in-addr"
+			if c.ip.To4() == nil {
+				value = "ip6"
+			}
+		case 'h':
+			value = c.helo
+		default:
+			return "", errors.New("spf: unknown macro letter")
+		}
+		transformed, err := transformMacro(value, macro[1:])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(transformed)
+	}
+	return strings.ToLower(strings.TrimSuffix(b.String(), ".")), nil
+}
+
+// transformMacro 按 "数字、r、分隔符" 变换宏的值：按分隔符拆分，可选倒序，保留右侧 N 段，以 "." 连接
+func transformMacro(value, transformers string) (string, error) {
+	digits := 0
+	for digits < len(transformers) && transformers[digits] >= '0' && transformers[digits] <= '9' {
+		digits++
+	}
+	keep := 0
+	if digits > 0 {
+		var err error
+		if keep, err = strconv.Atoi(transformers[:digits]); err != nil || keep == 0 {
+			return "", errors.New("spf: invalid macro transformer")
+		}
+	}
+	rest := transformers[digits:]
+	reverse := false
+	if rest != "" && (rest[0] == 'r' || rest[0] == 'R') {
+		reverse, rest = true, rest[1:]
+	}
+	delimiters := "."
+	if rest != "" {
+		if strings.Trim(rest, ".-+,/_=") != "" {
+			return "", errors.New("spf: invalid macro delimiter")
+		}
+		delimiters = rest
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(delimiters, r) })
+	if reverse {
+		for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+			parts[i], parts[j] = parts[j], parts[i]
+		}
+	}
+	if keep > 0 && keep < len(parts) {
+		parts = parts[len(parts)-keep:]
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// macroIP %{i} 的值：IPv4 为点分十进制，IPv6 为以点分隔的半字节
+func macroIP(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return v4.String()
+	}
+	const hex = "0123456789abcdef"
+	var b strings.Builder
+	for i, octet := range ip.To16() {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteByte(hex[octet>>4])
+		b.WriteByte('.')
+		b.WriteByte(hex[octet&0x0f])
+	}
+	return b.String()
+}
+
+// matchIPNetwork ip4:/ip6: 机制
+func matchIPNetwork(ip net.IP, arg string, v6 bool) (bool, string) {
+	addr, bitsText, hasBits := strings.Cut(arg, "/")
+	network := net.ParseIP(addr)
+	if network == nil || (network.To4() == nil) != v6 {
+		return false, domain.SPFResultPermError
+	}
+	bits := 32
+	if v6 {
+		bits = 128
+	}
+	if hasBits {
+		parsed, err := strconv.Atoi(bitsText)
+		if err != nil || parsed < 0 || parsed > bits {
+			return false, domain.SPFResultPermError
+		}
+		bits = parsed
+	}
+	if v6 {
+		return ipInRange(ip, network, 32, bits), ""
+	}
+	return ipInRange(ip, network, bits, 128), ""
+}
+
+// splitCIDR 拆分 a/mx 参数中的 domain-spec 与 IPv4、IPv6 前缀长度（"/24//64"）
+func splitCIDR(arg string) (spec string, ip4Bits, ip6Bits int, ok bool) {
+	ip4Bits, ip6Bits = 32, 128
+	spec = arg
+	if i := strings.Index(spec, "//"); i >= 0 {
+		bits, err := strconv.Atoi(spec[i+2:])
+		if err != nil || bits < 0 || bits > 128 {
+			return "", 0, 0, false
+		}
+		ip6Bits, spec = bits, spec[:i]
+	}
+	if i := strings.LastIndexByte(spec, '/'); i >= 0 {
+		bits, err := strconv.Atoi(spec[i+1:])
+		if err != nil || bits < 0 || bits > 32 {
+			return "", 0, 0, false
+		}
+		ip4Bits, spec = bits, spec[:i]
+	}
+	return spec, ip4Bits, ip6Bits, true
+}
+
+// ipInRange ip 是否与 network 同族且在对应前缀长度的网段内
+func ipInRange(ip, network net.IP, ip4Bits, ip6Bits int) bool {
+	if v4 := ip.To4(); v4 != nil {
+		n4 := network.To4()
+		if n4 == nil {
+			return false
+		}
+		mask := net.CIDRMask(ip4Bits, 32)
+		return v4.Mask(mask).Equal(n4.Mask(mask))
+	}
+	if network.To4() != nil {
+		return false
+	}
+	mask := net.CIDRMask(ip6Bits, 128)
+	return ip.To16().Mask(mask).Equal(network.To16().Mask(mask))
+}
+
+// isModifierName 修饰符名：字母开头，后跟字母、数字、-、_、.
+func isModifierName(name string) bool {
+	if name == "" || !isAlpha(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if c := name[i]; !isAlpha(c) && (c < '0' || c > '9') && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(c byte) bool {
+	return (c|0x20) >= 'a' && (c|0x20) <= 'z'
+}
+
+// validDomain 至少两级、每级 1-63 字节的域名
+func validDomain(name string) bool {
+	if len(name) == 0 || len(name) > 253 || !strings.Contains(name, ".") {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+	}
+	return true
+}
+
+// isNotFound 域名或记录不存在（区别于超时等临时错误）
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
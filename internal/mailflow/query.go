@@ -0,0 +1,175 @@
+package mailflow
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// 时间序列的粒度
+const (
+	IntervalMinute = "minute"
+	IntervalHour   = "hour"
+)
+
+const (
+	// MaxMinuteRange 按分钟查询的最大时间范围
+	MaxMinuteRange = 24 * time.Hour
+	// MaxHourRange 按小时查询的最大时间范围
+	MaxHourRange = 7 * 24 * time.Hour
+	// DefaultTop 默认返回的域名数
+	DefaultTop = 10
+	// MaxTop 最多返回的域名数
+	MaxTop = 100
+)
+
+var (
+	ErrInvalidInterval  = errors.New("invalid mail flow interval")
+	ErrInvalidTimeRange = errors.New("invalid mail flow time range")
+	ErrTimeRangeTooLong = errors.New("mail flow time range too long")
+)
+
+// Query 查询条件，From/To 为空时默认最近 1 小时（按小时为最近 24 小时）
+type Query struct {
+	Interval string // minute 或 hour，默认 minute
+	From     time.Time
+	To       time.Time
+	Top      int // 每个排行返回的域名数，默认 10，最多 100
+}
+
+// Point 时间序列中的一个点
+type Point struct {
+	Time     time.Time `json:"time"`
+	Accepted int64     `json:"accepted"`
+	Rejected int64     `json:"rejected"`
+}
+
+// Count 按维度（域名或拒绝原因）的合计
+type Count struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// Totals 范围内的合计
+type Totals struct {
+	Accepted int64 `json:"accepted"`
+	Rejected int64 `json:"rejected"`
+}
+
+// Report 收信流量报告
+type Report struct {
+	Interval string    `json:"interval"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Totals   Totals    `json:"totals"`
+	// AveragePerMinute 范围内平均每分钟接收的邮件数
+	AveragePerMinute float64 `json:"averagePerMinute"`
+	// PeakPerMinute 范围内单分钟接收邮件数的最大值（按小时查询时同样按分钟计算）
+	PeakPerMinute       int64   `json:"peakPerMinute"`
+	Points              []Point `json:"points"`
+	Rejections          []Count `json:"rejections"`
+	TopSenderDomains    []Count `json:"topSenderDomains"`
+	TopRecipientDomains []Count `json:"topRecipientDomains"`
+}
+
+// Query 查询收信流量（只含已写入存储的计数，最多延迟一个写入间隔）
+//
+// 范围按粒度对齐到 UTC 整分钟/整点，缺少数据的时间点补零。
+func (r *Recorder) Query(ctx context.Context, q Query) (*Report, error) {
+	var step, maxRange, defaultRange time.Duration
+	switch q.Interval {
+	case "", IntervalMinute:
+		q.Interval, step, maxRange, defaultRange = IntervalMinute, time.Minute, MaxMinuteRange, time.Hour
+	case IntervalHour:
+		step, maxRange, defaultRange = time.Hour, MaxHourRange, 24*time.Hour
+	default:
+		return nil, ErrInvalidInterval
+	}
+	top := q.Top
+	if top <= 0 {
+		top = DefaultTop
+	}
+	top = min(top, MaxTop)
+
+	to := q.To.UTC()
+	if q.To.IsZero() {
+		to = r.now().UTC()
+	}
+	if to.Truncate(step) != to {
+		to = to.Truncate(step).Add(step)
+	}
+	from := q.From.UTC().Truncate(step)
+	if q.From.IsZero() {
+		from = to.Add(-defaultRange)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidTimeRange
+	}
+	if to.Sub(from) > maxRange {
+		return nil, ErrTimeRangeTooLong
+	}
+
+	counters, err := r.store.ListMailFlowCounters(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := make(map[time.Time]int64)
+	rejected := make(map[time.Time]int64)
+	perMinute := make(map[time.Time]int64)
+	dimensions := map[string]map[string]int64{
+		domain.MailFlowRejected:        {},
+		domain.MailFlowSenderDomain:    {},
+		domain.MailFlowRecipientDomain: {},
+	}
+	report := &Report{Interval: q.Interval, From: from, To: to}
+	for _, counter := range counters {
+		at := counter.Bucket.UTC().Truncate(step)
+		switch counter.Kind {
+		case domain.MailFlowAccepted:
+			accepted[at] += counter.Count
+			perMinute[counter.Bucket.UTC()] += counter.Count
+			report.Totals.Accepted += counter.Count
+		case domain.MailFlowRejected:
+			rejected[at] += counter.Count
+			report.Totals.Rejected += counter.Count
+		}
+		if byName, ok := dimensions[counter.Kind]; ok {
+			byName[counter.Dimension] += counter.Count
+		}
+	}
+
+	report.Points = make([]Point, 0, int(to.Sub(from)/step))
+	for at := from; at.Before(to); at = at.Add(step) {
+		report.Points = append(report.Points, Point{Time: at, Accepted: accepted[at], Rejected: rejected[at]})
+	}
+	for _, count := range perMinute {
+		report.PeakPerMinute = max(report.PeakPerMinute, count)
+	}
+	report.AveragePerMinute = float64(report.Totals.Accepted) / to.Sub(from).Minutes()
+	report.Rejections = ranked(dimensions[domain.MailFlowRejected], MaxTop)
+	report.TopSenderDomains = ranked(dimensions[domain.MailFlowSenderDomain], top)
+	report.TopRecipientDomains = ranked(dimensions[domain.MailFlowRecipientDomain], top)
+	return report, nil
+}
+
+// ranked 按合计从高到低返回前 limit 个维度（合计相同时按名称排序）
+func ranked(counts map[string]int64, limit int) []Count {
+	result := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, Count{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
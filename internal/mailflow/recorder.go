@@ -0,0 +1,209 @@
+// Package mailflow 统计 SMTP 收信流量，供管理后台查看流量突增（不依赖 Prometheus）。
+//
+// SMTP 后端每接收一封邮件或拒收一次时调用记录器，计数先在内存中按 UTC 分钟合并，定期写入主存储；
+// 多个实例写入同一分钟时累加。只保存域名和拒绝原因，不保存完整地址。
+package mailflow
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+const (
+	// DefaultRetention 默认保留期限
+	DefaultRetention = 7 * 24 * time.Hour
+	// DefaultFlushInterval 默认写入间隔
+	DefaultFlushInterval = 10 * time.Second
+	// flushTimeout 关闭时最后一次写入的超时
+	flushTimeout = 5 * time.Second
+	// pruneInterval 清理过期计数的间隔
+	pruneInterval = time.Hour
+)
+
+// counterKey 内存中待写入的计数
+type counterKey struct {
+	kind      string
+	dimension string
+	bucket    time.Time
+}
+
+// Recorder 收信流量记录器
+//
+// 实现 smtp.MailFlowRecorder；未启用时所有记录方法为空操作。
+type Recorder struct {
+	store         storage.MailFlowRepository
+	log           *zap.Logger
+	now           func() time.Time
+	enabled       bool
+	retention     time.Duration
+	flushInterval time.Duration
+
+	mu        sync.Mutex
+	pending   map[counterKey]int64 // 尚未写入的计数
+	lastPrune time.Time
+}
+
+// NewRecorder 创建收信流量记录器
+func NewRecorder(store storage.MailFlowRepository, cfg config.MailFlowConfig, log *zap.Logger) *Recorder {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	retention := cfg.Retention
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	flushInterval := cfg.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+	return &Recorder{
+		store:         store,
+		log:           log,
+		now:           time.Now,
+		enabled:       cfg.Enabled,
+		retention:     retention,
+		flushInterval: flushInterval,
+		pending:       make(map[counterKey]int64),
+	}
+}
+
+// SetClock 设置时间来源（测试用）
+func (r *Recorder) SetClock(now func() time.Time) {
+	r.now = now
+}
+
+// Enabled 是否统计
+func (r *Recorder) Enabled() bool {
+	return r.enabled
+}
+
+// RecordMessage 记录一封接收的邮件：计入接收数、发件域名和各收件域名（同一域名的多个收件人只计一次）
+func (r *Recorder) RecordMessage(from string, recipients []string) {
+	if !r.enabled {
+		return
+	}
+	now := r.now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addLocked(domain.MailFlowAccepted, "", now)
+	if senderDomain := addressDomain(from); senderDomain != "" {
+		r.addLocked(domain.MailFlowSenderDomain, senderDomain, now)
+	}
+	seen := make(map[string]bool, len(recipients))
+	for _, rcpt := range recipients {
+		recipientDomain := addressDomain(rcpt)
+		if recipientDomain == "" || seen[recipientDomain] {
+			continue
+		}
+		seen[recipientDomain] = true
+		r.addLocked(domain.MailFlowRecipientDomain, recipientDomain, now)
+	}
+}
+
+// RecordRejection 记录一次拒收（reason 为 smtp.RejectReasonXxx）
+func (r *Recorder) RecordRejection(reason string) {
+	if !r.enabled {
+		return
+	}
+	now := r.now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addLocked(domain.MailFlowRejected, reason, now)
+}
+
+func (r *Recorder) addLocked(kind, dimension string, at time.Time) {
+	r.pending[counterKey{kind: kind, dimension: dimension, bucket: at.Truncate(time.Minute)}]++
+}
+
+// Flush 把内存中的计数写入存储，失败时计数保留到下次写入
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	if len(r.pending) == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	pending := r.pending
+	r.pending = make(map[counterKey]int64)
+	r.mu.Unlock()
+
+	counters := make([]domain.MailFlowCounter, 0, len(pending))
+	for key, count := range pending {
+		counters = append(counters, domain.MailFlowCounter{Kind: key.kind, Dimension: key.dimension, Bucket: key.bucket, Count: count})
+	}
+	if err := r.store.MergeMailFlowCounters(ctx, counters); err != nil {
+		r.mu.Lock()
+		for key, count := range pending {
+			r.pending[key] += count
+		}
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Prune 删除超过保留期限的计数
+func (r *Recorder) Prune(ctx context.Context) (int64, error) {
+	cutoff := r.now().UTC().Add(-r.retention).Truncate(time.Minute)
+	return r.store.DeleteMailFlowCountersBefore(ctx, cutoff)
+}
+
+// Run 定期写入计数并清理过期数据，ctx 取消时写入剩余计数后返回；未启用时立即返回
+func (r *Recorder) Run(ctx context.Context) {
+	if !r.enabled {
+		return
+	}
+
+	r.prune(ctx)
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+			if err := r.Flush(flushCtx); err != nil {
+				r.log.Warn("failed to flush mail flow counters on shutdown", zap.Error(err))
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := r.Flush(ctx); err != nil {
+				r.log.Warn("failed to flush mail flow counters", zap.Error(err))
+			}
+			if r.now().Sub(r.lastPrune) >= pruneInterval {
+				r.prune(ctx)
+			}
+		}
+	}
+}
+
+// prune 清理过期数据（失败只记日志，下个周期重试）
+func (r *Recorder) prune(ctx context.Context) {
+	deleted, err := r.Prune(ctx)
+	if err != nil {
+		r.log.Warn("failed to prune mail flow counters", zap.Error(err))
+		return
+	}
+	r.lastPrune = r.now()
+	if deleted > 0 {
+		r.log.Debug("pruned mail flow counters", zap.Int64("deleted", deleted))
+	}
+}
+
+// addressDomain 信封地址的域名部分（小写），空发件人或无法解析时为空
+func addressDomain(address string) string {
+	address = strings.Trim(strings.TrimSpace(address), "<>")
+	at := strings.LastIndex(address, "@")
+	if at < 0 || at == len(address)-1 {
+		return ""
+	}
+	return strings.ToLower(address[at+1:])
+}
@@ -0,0 +1,110 @@
+package mailflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func newTestRecorder(cfg config.MailFlowConfig) (*Recorder, *memory.Store, *time.Time) {
+	store := memory.NewStore(time.Hour)
+	recorder := NewRecorder(store, cfg, nil)
+	now := time.Date(2026, 3, 1, 12, 0, 30, 0, time.UTC)
+	recorder.SetClock(func() time.Time { return now })
+	return recorder, store, &now
+}
+
+func TestRecorder(t *testing.T) {
+	t.Run("按分钟汇总接收数、拒收原因和域名排行", func(t *testing.T) {
+		recorder, _, now := newTestRecorder(config.MailFlowConfig{Enabled: true})
+
+		recorder.RecordMessage("a@Sender.com", []string{"x@temp.mail", "y@temp.mail", "z@other.mail"})
+		recorder.RecordMessage("<>", []string{"x@temp.mail"})
+		recorder.RecordRejection("greylisted")
+		*now = now.Add(time.Minute)
+		recorder.RecordMessage("b@sender.com", []string{"x@other.mail"})
+		recorder.RecordMessage("c@spam.example", []string{"x@temp.mail"})
+		recorder.RecordMessage("d@spam.example", []string{"x@temp.mail"})
+		recorder.RecordRejection("spam")
+		recorder.RecordRejection("spam")
+		require.NoError(t, recorder.Flush(t.Context()))
+
+		report, err := recorder.Query(t.Context(), Query{
+			From: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+			To:   time.Date(2026, 3, 1, 12, 3, 0, 0, time.UTC),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, IntervalMinute, report.Interval)
+		assert.Equal(t, Totals{Accepted: 5, Rejected: 3}, report.Totals)
+		assert.Equal(t, int64(3), report.PeakPerMinute)
+		assert.InDelta(t, 5.0/3, report.AveragePerMinute, 0.001)
+		require.Len(t, report.Points, 3)
+		assert.Equal(t, Point{Time: report.From, Accepted: 2, Rejected: 1}, report.Points[0])
+		assert.Equal(t, int64(3), report.Points[1].Accepted)
+		assert.Zero(t, report.Points[2].Accepted)
+		assert.Equal(t, []Count{{"spam", 2}, {"greylisted", 1}}, report.Rejections)
+		assert.Equal(t, []Count{{"sender.com", 2}, {"spam.example", 2}}, report.TopSenderDomains)
+		// 同一封邮件的多个同域收件人只计一次
+		assert.Equal(t, []Count{{"temp.mail", 4}, {"other.mail", 2}}, report.TopRecipientDomains)
+
+		hourly, err := recorder.Query(t.Context(), Query{Interval: IntervalHour, Top: 1})
+		require.NoError(t, err)
+		assert.Len(t, hourly.Points, 24)
+		assert.Equal(t, Totals{Accepted: 5, Rejected: 3}, hourly.Totals)
+		assert.Equal(t, int64(3), hourly.PeakPerMinute)
+		assert.Len(t, hourly.TopRecipientDomains, 1)
+	})
+
+	t.Run("多次写入同一分钟累加", func(t *testing.T) {
+		recorder, _, _ := newTestRecorder(config.MailFlowConfig{Enabled: true})
+		recorder.RecordMessage("a@sender.com", []string{"x@temp.mail"})
+		require.NoError(t, recorder.Flush(t.Context()))
+		recorder.RecordMessage("a@sender.com", []string{"x@temp.mail"})
+		require.NoError(t, recorder.Flush(t.Context()))
+
+		report, err := recorder.Query(t.Context(), Query{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), report.Totals.Accepted)
+		assert.Equal(t, []Count{{"sender.com", 2}}, report.TopSenderDomains)
+	})
+
+	t.Run("未启用时不记录", func(t *testing.T) {
+		recorder, _, _ := newTestRecorder(config.MailFlowConfig{})
+		recorder.RecordMessage("a@sender.com", []string{"x@temp.mail"})
+		recorder.RecordRejection("spam")
+		require.NoError(t, recorder.Flush(t.Context()))
+
+		report, err := recorder.Query(t.Context(), Query{})
+		require.NoError(t, err)
+		assert.Zero(t, report.Totals)
+	})
+
+	t.Run("清理超过保留期限的计数", func(t *testing.T) {
+		recorder, store, now := newTestRecorder(config.MailFlowConfig{Enabled: true, Retention: time.Hour})
+		recorder.RecordMessage("a@sender.com", []string{"x@temp.mail"})
+		require.NoError(t, recorder.Flush(t.Context()))
+		*now = now.Add(2 * time.Hour)
+
+		deleted, err := recorder.Prune(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), deleted)
+		counters, err := store.ListMailFlowCounters(t.Context(), time.Time{}, *now)
+		require.NoError(t, err)
+		assert.Empty(t, counters)
+	})
+
+	t.Run("无效的查询条件", func(t *testing.T) {
+		recorder, _, now := newTestRecorder(config.MailFlowConfig{Enabled: true})
+		_, err := recorder.Query(t.Context(), Query{Interval: "day"})
+		assert.ErrorIs(t, err, ErrInvalidInterval)
+		_, err = recorder.Query(t.Context(), Query{From: *now, To: now.Add(-time.Hour)})
+		assert.ErrorIs(t, err, ErrInvalidTimeRange)
+		_, err = recorder.Query(t.Context(), Query{From: now.Add(-48 * time.Hour), To: *now})
+		assert.ErrorIs(t, err, ErrTimeRangeTooLong)
+	})
+}
@@ -0,0 +1,71 @@
+package mailfmt
+
+import (
+	"bytes"
+	"fmt"
+	"mime/quotedprintable"
+	"strings"
+	"time"
+)
+
+// Outgoing 待发出的邮件
+type Outgoing struct {
+	From       string   // 发件人地址
+	To         []string // 收件人（已格式化的地址，如 mail.Address.String() 的结果）
+	Cc         []string
+	Subject    string
+	Text       string
+	HTML       string
+	MessageID  string // 不含尖括号
+	InReplyTo  string // 回复的邮件的 Message-ID（不含尖括号，可选）
+	References []string
+	Date       time.Time
+}
+
+// Compose 生成 CRLF 换行的邮件：正文为 UTF-8 quoted-printable，同时有纯文本和 HTML 时为 multipart/alternative
+func Compose(o Outgoing) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Date: %s\r\n", o.Date.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "From: %s\r\n", HeaderValue(o.From))
+	fmt.Fprintf(&b, "To: %s\r\n", HeaderValue(strings.Join(o.To, ", ")))
+	if len(o.Cc) > 0 {
+		fmt.Fprintf(&b, "Cc: %s\r\n", HeaderValue(strings.Join(o.Cc, ", ")))
+	}
+	fmt.Fprintf(&b, "Subject: %s\r\n", HeaderValue(o.Subject))
+	fmt.Fprintf(&b, "Message-ID: <%s>\r\n", o.MessageID)
+	if o.InReplyTo != "" {
+		fmt.Fprintf(&b, "In-Reply-To: <%s>\r\n", o.InReplyTo)
+	}
+	if len(o.References) > 0 {
+		fmt.Fprintf(&b, "References: <%s>\r\n", strings.Join(o.References, "> <"))
+	}
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	switch {
+	case o.Text != "" && o.HTML != "":
+		boundary := "=_" + strings.NewReplacer("@", "_", ".", "_").Replace(o.MessageID)
+		fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=\"%s\"\r\n\r\n", boundary)
+		for _, part := range []struct{ contentType, body string }{{"text/plain", o.Text}, {"text/html", o.HTML}} {
+			fmt.Fprintf(&b, "--%s\r\n", boundary)
+			writeTextPart(&b, part.contentType, part.body)
+			b.WriteString("\r\n")
+		}
+		fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	case o.HTML != "":
+		writeTextPart(&b, "text/html", o.HTML)
+	default:
+		writeTextPart(&b, "text/plain", o.Text)
+	}
+	return b.Bytes()
+}
+
+func writeTextPart(b *bytes.Buffer, contentType, body string) {
+	fmt.Fprintf(b, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(b)
+	_, _ = w.Write([]byte(body))
+	_ = w.Close()
+	if !bytes.HasSuffix(b.Bytes(), []byte("\r\n")) {
+		b.WriteString("\r\n")
+	}
+}
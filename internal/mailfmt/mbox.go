@@ -0,0 +1,34 @@
+package mailfmt
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteMbox 以 mboxrd 格式追加一封邮件
+//
+// 分隔行为 "From <信封发件人> <asctime>"，换行统一为 LF，正文中以 ">*From " 开头的行再加一个 ">"，
+// 邮件之间空一行。content 通常是 Render 的结果。
+func WriteMbox(w io.Writer, sender string, received time.Time, content []byte) error {
+	sender = strings.Join(strings.Fields(sender), "")
+	if sender == "" {
+		sender = "MAILER-DAEMON"
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString("From " + sender + " " + received.UTC().Format(time.ANSIC) + "\n")
+
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	content = bytes.TrimSuffix(content, []byte("\n"))
+	for line := range bytes.SplitSeq(content, []byte("\n")) {
+		if bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")) {
+			bw.WriteByte('>')
+		}
+		bw.Write(line)
+		bw.WriteByte('\n')
+	}
+	bw.WriteByte('\n')
+	return bw.Flush()
+}
@@ -0,0 +1,26 @@
+package mailfmt
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMbox(t *testing.T) {
+	received := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("分隔行、LF 换行和 From 行转义", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteMbox(&buf, "a@example.com", received, []byte("Subject: hi\r\n\r\nFrom here\r\n>From there\r\nok\r\n")))
+		assert.Equal(t, "From a@example.com Sun Mar  1 12:00:00 2026\nSubject: hi\n\n>From here\n>>From there\nok\n\n", buf.String())
+	})
+
+	t.Run("没有发件人时使用 MAILER-DAEMON", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteMbox(&buf, " ", received, []byte("Subject: hi\r\n\r\nbody")))
+		assert.Equal(t, "From MAILER-DAEMON Sun Mar  1 12:00:00 2026\nSubject: hi\n\nbody\n\n", buf.String())
+	})
+}
@@ -0,0 +1,66 @@
+// Package mailfmt 邮件内容的线路格式（POP3、IMAP 收信和发信中继共用）
+package mailfmt
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tempmail/backend/internal/domain"
+)
+
+// Render 返回邮件的 CRLF 换行内容（以 CRLF 结尾）
+//
+// 没有原始邮件时（未配置文件系统存储或原始内容缺失）由元数据和正文生成一封单部分邮件，
+// Message-ID 为 <邮件ID@serverName>。
+func Render(message *domain.Message, serverName string) []byte {
+	raw := message.Raw
+	if raw == "" {
+		raw = synthesize(message, serverName)
+	}
+	normalized := strings.ReplaceAll(raw, "\r\n", "\n")
+	normalized = strings.ReplaceAll(normalized, "\r", "\n")
+	if !strings.HasSuffix(normalized, "\n") {
+		normalized += "\n"
+	}
+	return []byte(strings.ReplaceAll(normalized, "\n", "\r\n"))
+}
+
+func synthesize(message *domain.Message, serverName string) string {
+	if serverName == "" {
+		serverName = "localhost"
+	}
+	body, contentType := message.Text, "text/plain"
+	if body == "" && message.HTML != "" {
+		body, contentType = message.HTML, "text/html"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\n", HeaderValue(message.From))
+	fmt.Fprintf(&b, "To: %s\n", HeaderValue(message.To))
+	fmt.Fprintf(&b, "Subject: %s\n", HeaderValue(message.Subject))
+	fmt.Fprintf(&b, "Date: %s\n", message.ReceivedAt.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\n", message.ID, serverName)
+	b.WriteString("MIME-Version: 1.0\n")
+	fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\n", contentType)
+	b.WriteString("Content-Transfer-Encoding: 8bit\n\n")
+	b.WriteString(body)
+	return b.String()
+}
+
+// HeaderValue 去除换行，非 ASCII 内容按 RFC 2047 编码
+func HeaderValue(value string) string {
+	return EncodeWord(strings.NewReplacer("\r", " ", "\n", " ").Replace(value))
+}
+
+// EncodeWord 非 ASCII 内容按 RFC 2047 B 编码，纯 ASCII 原样返回
+func EncodeWord(value string) string {
+	for i := 0; i < len(value); i++ {
+		if value[i] >= utf8.RuneSelf {
+			return mime.BEncoding.Encode("utf-8", value)
+		}
+	}
+	return value
+}
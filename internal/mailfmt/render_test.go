@@ -0,0 +1,58 @@
+package mailfmt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"tempmail/backend/internal/domain"
+)
+
+func TestRender(t *testing.T) {
+	t.Run("原始邮件统一为 CRLF 换行", func(t *testing.T) {
+		content := string(Render(&domain.Message{Raw: "Subject: hi\n\nline 1\r\nline 2"}, ""))
+		assert.Equal(t, "Subject: hi\r\n\r\nline 1\r\nline 2\r\n", content)
+	})
+
+	t.Run("没有原始邮件时由元数据生成", func(t *testing.T) {
+		content := string(Render(&domain.Message{
+			ID: "msg-1", From: "a@example.com", To: "b@example.com", Subject: "你好",
+			Text: "line\n.dot", ReceivedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		}, "temp.example"))
+
+		assert.Contains(t, content, "Subject: =?utf-8?b?")
+		assert.Contains(t, content, "Message-ID: <msg-1@temp.example>\r\n")
+		assert.Contains(t, content, "Date: Sun, 01 Mar 2026 12:00:00 +0000\r\n")
+		assert.True(t, strings.HasSuffix(content, "\r\n\r\nline\r\n.dot\r\n"), content)
+		assert.NotContains(t, strings.ReplaceAll(content, "\r\n", ""), "\n")
+	})
+}
+
+func TestCompose(t *testing.T) {
+	outgoing := Outgoing{
+		From: "team@temp.example", To: []string{"<a@example.com>"}, Subject: "Re: 发票",
+		Text: "已付款", MessageID: "id-1@temp.example", InReplyTo: "orig@vendor.example",
+		References: []string{"root@vendor.example", "orig@vendor.example"},
+		Date:       time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("纯文本", func(t *testing.T) {
+		content := string(Compose(outgoing))
+		assert.Contains(t, content, "Subject: =?utf-8?b?")
+		assert.Contains(t, content, "In-Reply-To: <orig@vendor.example>\r\n")
+		assert.Contains(t, content, "References: <root@vendor.example> <orig@vendor.example>\r\n")
+		assert.Contains(t, content, "Content-Type: text/plain; charset=utf-8\r\n")
+		assert.NotContains(t, content, "multipart")
+		assert.NotContains(t, strings.ReplaceAll(content, "\r\n", ""), "\n")
+	})
+
+	t.Run("同时有 HTML 时为 multipart/alternative", func(t *testing.T) {
+		outgoing.HTML = "<p>已付款</p>"
+		content := string(Compose(outgoing))
+		assert.Contains(t, content, `Content-Type: multipart/alternative; boundary="=_id-1_temp_example"`)
+		assert.Contains(t, content, "Content-Type: text/html; charset=utf-8\r\n")
+		assert.True(t, strings.HasSuffix(content, "--=_id-1_temp_example--\r\n"))
+	})
+}
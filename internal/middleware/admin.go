@@ -9,9 +9,18 @@ import (
 	"tempmail/backend/internal/domain"
 )
 
+// ErrorCodeTwoFactorRequired 管理员未启用两步验证（系统配置要求时）
+const ErrorCodeTwoFactorRequired = "TWO_FACTOR_REQUIRED"
+
+// TwoFactorPolicy 管理员两步验证要求提供者
+type TwoFactorPolicy interface {
+	RequireAdminTwoFactor() bool
+}
+
 // AdminAuth 管理员权限中间件
 type AdminAuth struct {
 	authService *auth.Service
+	twoFactor   TwoFactorPolicy // 可选：要求管理员启用两步验证
 }
 
 // NewAdminAuth 创建管理员权限中间件
@@ -21,6 +30,21 @@ func NewAdminAuth(authService *auth.Service) *AdminAuth {
 	}
 }
 
+// SetTwoFactorPolicy 设置管理员两步验证要求（系统配置开启后未启用两步验证的管理员被拒绝）
+func (a *AdminAuth) SetTwoFactorPolicy(policy TwoFactorPolicy) {
+	a.twoFactor = policy
+}
+
+// rejectWithoutTwoFactor 系统要求两步验证而管理员未启用时返回 403（仍可通过 /v1/auth/2fa 启用）
+func (a *AdminAuth) rejectWithoutTwoFactor(c *gin.Context, user *domain.User) bool {
+	if a.twoFactor == nil || !user.IsAdmin() || user.TOTPEnabled || !a.twoFactor.RequireAdminTwoFactor() {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication required", "code": ErrorCodeTwoFactorRequired})
+	c.Abort()
+	return true
+}
+
 // RequireAdmin 要求管理员权限（Admin或Super）
 func (a *AdminAuth) RequireAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -40,7 +64,7 @@ func (a *AdminAuth) RequireAdmin() gin.HandlerFunc {
 		}
 
 		// 获取用户信息
-		user, err := a.authService.GetUserByID(userID)
+		user, err := a.authService.GetUserByID(c.Request.Context(), userID)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 			c.Abort()
@@ -60,6 +84,9 @@ func (a *AdminAuth) RequireAdmin() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if a.rejectWithoutTwoFactor(c, user) {
+			return
+		}
 
 		// 将用户信息存入上下文
 		c.Set("user", user)
@@ -87,7 +114,7 @@ func (a *AdminAuth) RequireSuper() gin.HandlerFunc {
 		}
 
 		// 获取用户信息
-		user, err := a.authService.GetUserByID(userID)
+		user, err := a.authService.GetUserByID(c.Request.Context(), userID)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 			c.Abort()
@@ -107,6 +134,9 @@ func (a *AdminAuth) RequireSuper() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if a.rejectWithoutTwoFactor(c, user) {
+			return
+		}
 
 		// 将用户信息存入上下文
 		c.Set("user", user)
@@ -134,7 +164,7 @@ func (a *AdminAuth) RequireRole(allowedRoles ...domain.UserRole) gin.HandlerFunc
 		}
 
 		// 获取用户信息
-		user, err := a.authService.GetUserByID(userID)
+		user, err := a.authService.GetUserByID(c.Request.Context(), userID)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 			c.Abort()
@@ -155,6 +185,9 @@ func (a *AdminAuth) RequireRole(allowedRoles ...domain.UserRole) gin.HandlerFunc
 			c.Abort()
 			return
 		}
+		if a.rejectWithoutTwoFactor(c, user) {
+			return
+		}
 
 		// 将用户信息存入上下文
 		c.Set("user", user)
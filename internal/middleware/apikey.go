@@ -10,6 +10,7 @@ import (
 // APIKeyAuth API Key认证中间件
 type APIKeyAuth struct {
 	apiKeyService *service.APIKeyService
+	quota         *RequestQuota // 可选：按用户等级限制每分钟请求数
 }
 
 // NewAPIKeyAuth 创建API Key认证中间件
@@ -19,6 +20,11 @@ func NewAPIKeyAuth(apiKeyService *service.APIKeyService) *APIKeyAuth {
 	}
 }
 
+// SetRequestQuota 按 API Key 所属用户的等级计入每分钟请求配额
+func (m *APIKeyAuth) SetRequestQuota(quota *RequestQuota) {
+	m.quota = quota
+}
+
 // RequireAPIKey 要求API Key认证
 func (m *APIKeyAuth) RequireAPIKey() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -32,7 +38,7 @@ func (m *APIKeyAuth) RequireAPIKey() gin.HandlerFunc {
 		}
 
 		// 验证API Key并自动更新最后使用时间
-		user, err := m.apiKeyService.ValidateAPIKey(apiKey)
+		user, err := m.apiKeyService.ValidateAPIKey(c.Request.Context(), apiKey)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "invalid API key",
@@ -43,7 +49,11 @@ func (m *APIKeyAuth) RequireAPIKey() gin.HandlerFunc {
 
 		// 将用户ID存入上下文
 		c.Set("userID", user.ID)
+		c.Set("tier", string(user.Tier))
 		c.Set("user", user)
+		if !m.quota.allow(c) {
+			return
+		}
 
 		c.Next()
 	}
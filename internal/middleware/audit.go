@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/domain"
+)
+
+// auditEntryKey 上下文中待写入的审计记录
+const auditEntryKey = "auditEntry"
+
+// AuditRecorder 管理操作审计记录的写入
+type AuditRecorder interface {
+	Record(ctx context.Context, log *domain.AuditLog) error
+}
+
+// AdminAudit 管理操作审计中间件：写操作（非 GET/HEAD/OPTIONS）成功（2xx）后记录操作者、操作和目标
+//
+// 处理器可通过 AuditEntry 补充操作名、目标和字段变更；未命名的操作记为 "方法 路由"，目标默认为路径参数 id。
+// 写入失败只记日志，不影响已返回的响应。
+func AdminAudit(recorder AuditRecorder, log *zap.Logger) gin.HandlerFunc {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	return func(c *gin.Context) {
+		if readOnlyMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		entry := &domain.AuditLog{}
+		c.Set(auditEntryKey, entry)
+		c.Next()
+
+		if recorder == nil {
+			return
+		}
+		if status := c.Writer.Status(); status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+		if entry.Action == "" {
+			entry.Action = c.Request.Method + " " + c.FullPath()
+		}
+		if entry.TargetID == "" {
+			entry.TargetID = c.Param("id")
+		}
+		entry.ActorID = c.GetString("userID")
+		entry.ActorEmail = c.GetString("email")
+		entry.IP = c.ClientIP()
+		if err := recorder.Record(context.WithoutCancel(c.Request.Context()), entry); err != nil {
+			log.Error("failed to record audit log",
+				zap.String("action", entry.Action),
+				zap.String("actor_id", entry.ActorID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// AuditEntry 当前请求待写入的审计记录（不在审计范围内时返回一个不会写入的空记录，调用方无需判空）
+func AuditEntry(c *gin.Context) *domain.AuditLog {
+	if value, ok := c.Get(auditEntryKey); ok {
+		if entry, ok := value.(*domain.AuditLog); ok {
+			return entry
+		}
+	}
+	return &domain.AuditLog{}
+}
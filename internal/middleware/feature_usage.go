@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureRecorder 功能调用统计
+type FeatureRecorder interface {
+	RecordFeature(feature string)
+}
+
+// FeatureUsage 功能调用统计中间件：请求成功（2xx）时记录一次调用，不记录请求内容和调用方
+func FeatureUsage(recorder FeatureRecorder, feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if recorder == nil {
+			return
+		}
+		if status := c.Writer.Status(); status >= http.StatusOK && status < http.StatusMultipleChoices {
+			recorder.RecordFeature(feature)
+		}
+	}
+}
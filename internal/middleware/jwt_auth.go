@@ -8,11 +8,14 @@ import (
 	"go.uber.org/zap"
 
 	"tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/service"
 )
 
 // JWTAuth JWT认证中间件
 type JWTAuth struct {
 	jwtManager *jwt.Manager
+	apiKeys    *service.APIKeyService // 可选：OptionalAuth 接受 API Key
+	quota      *RequestQuota          // 可选：按用户等级限制每分钟请求数
 	log        *zap.Logger
 }
 
@@ -24,6 +27,16 @@ func NewJWTAuth(jwtManager *jwt.Manager) *JWTAuth {
 	}
 }
 
+// SetAPIKeys 允许 OptionalAuth 以 API Key（X-API-Key）识别用户，供 CLI 和脚本创建归属自己的邮箱
+func (ja *JWTAuth) SetAPIKeys(apiKeys *service.APIKeyService) {
+	ja.apiKeys = apiKeys
+}
+
+// SetRequestQuota 识别出用户后按等级计入每分钟请求配额
+func (ja *JWTAuth) SetRequestQuota(quota *RequestQuota) {
+	ja.quota = quota
+}
+
 // RequireAuth 要求JWT认证
 func (ja *JWTAuth) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -36,7 +49,7 @@ func (ja *JWTAuth) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := ja.jwtManager.ValidateToken(token)
+		claims, err := ja.jwtManager.Authenticate(c.Request.Context(), token)
 		if err != nil {
 			ja.log.Warn("invalid token",
 				zap.String("error", err.Error()),
@@ -49,36 +62,120 @@ func (ja *JWTAuth) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
+		if !impersonationAllowed(c, claims) {
+			return
+		}
+
 		// 将用户信息存储到上下文
+		c.Set("claims", claims) // 注销、会话列表使用 jti 和 sid
 		c.Set("userID", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("tier", claims.Tier)
+		ja.setOrgClaims(c, claims)
+		if !ja.quota.allow(c) {
+			return
+		}
 
 		c.Next()
 	}
 }
 
+// impersonationAllowed 代登录令牌只能发起只读请求，写请求返回 403；允许时在上下文记录代登录的管理员
+func impersonationAllowed(c *gin.Context, claims *jwt.Claims) bool {
+	if claims.Impersonator == "" {
+		return true
+	}
+	if !readOnlyMethod(c.Request.Method) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "impersonation tokens are read-only",
+			"code":  "IMPERSONATION_READ_ONLY",
+		})
+		c.Abort()
+		return false
+	}
+	c.Set("impersonatorID", claims.Impersonator)
+	return true
+}
+
+// ErrorCodeImpersonationForbidden 代登录令牌访问凭据类接口
+const ErrorCodeImpersonationForbidden = "IMPERSONATION_FORBIDDEN"
+
+// DenyImpersonation 拒绝代登录令牌（放在 RequireAuth 之后）
+//
+// 用于签发或查看凭据的路由（应用密码、API Key、会话、两步验证）：这些接口即使是 GET 也会泄露长期有效的凭据，
+// 只读限制不足以保护，代登录的管理员一律不可访问。
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("impersonatorID") != "" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "impersonation tokens cannot access credentials",
+				"code":  ErrorCodeImpersonationForbidden,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// readOnlyMethod 是否为只读请求方法
+func readOnlyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
 // OptionalAuth 可选的JWT认证
 func (ja *JWTAuth) OptionalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := ja.extractToken(c)
 		if token == "" {
-			c.Next()
-			return
-		}
-
-		claims, err := ja.jwtManager.ValidateToken(token)
-		if err == nil {
+			ja.apiKeyAuth(c)
+		} else if claims, err := ja.jwtManager.Authenticate(c.Request.Context(), token); err == nil {
+			if !impersonationAllowed(c, claims) {
+				return
+			}
 			c.Set("userID", claims.UserID)
 			c.Set("email", claims.Email)
 			c.Set("tier", claims.Tier)
 			c.Set("authenticated", true)
+			ja.setOrgClaims(c, claims)
+		}
+		if !ja.quota.allow(c) {
+			return
 		}
 
 		c.Next()
 	}
 }
 
+// apiKeyAuth 按 X-API-Key 识别用户（无效时按游客处理）
+func (ja *JWTAuth) apiKeyAuth(c *gin.Context) {
+	apiKey := c.GetHeader("X-API-Key")
+	if ja.apiKeys == nil || apiKey == "" {
+		return
+	}
+	user, err := ja.apiKeys.ValidateAPIKey(c.Request.Context(), apiKey)
+	if err != nil {
+		return
+	}
+	c.Set("userID", user.ID)
+	c.Set("email", user.Email)
+	c.Set("tier", string(user.Tier))
+	c.Set("authenticated", true)
+}
+
+// setOrgClaims 存储组织声明；成员关系已变化时通过响应头提示客户端刷新令牌
+// （授权判断始终以实时成员关系为准，令牌中的声明仅供客户端展示）
+func (ja *JWTAuth) setOrgClaims(c *gin.Context, claims *jwt.Claims) {
+	c.Set("orgs", claims.Orgs)
+	if ja.jwtManager.OrgClaimsStale(c.Request.Context(), claims) {
+		c.Header("X-Org-Claims-Stale", "true")
+	}
+}
+
 // extractToken 从请求中提取JWT token
 func (ja *JWTAuth) extractToken(c *gin.Context) string {
 	// 1. 从 Authorization header 提取
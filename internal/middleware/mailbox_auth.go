@@ -7,12 +7,18 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
 	"tempmail/backend/internal/service"
 )
 
 // MailboxAuth 邮箱Token认证中间件
 type MailboxAuth struct {
 	mailboxService *service.MailboxService
+	jwtManager     *jwt.Manager           // 可选：允许有权限的登录用户以 JWT 访问
+	authz          *service.Authorizer    // 可选：用户/组织权限判断
+	apiKeys        *service.APIKeyService // 可选：允许有权限的用户以 API Key 访问
+	quota          *RequestQuota          // 可选：以 JWT、API Key 访问时按用户等级限制每分钟请求数
 	log            *zap.Logger
 }
 
@@ -24,6 +30,59 @@ func NewMailboxAuth(mailboxService *service.MailboxService) *MailboxAuth {
 	}
 }
 
+// SetUserAccess 允许邮箱所有者及所在组织成员使用 JWT 代替邮箱Token
+func (ma *MailboxAuth) SetUserAccess(jwtManager *jwt.Manager, authz *service.Authorizer) {
+	ma.jwtManager = jwtManager
+	ma.authz = authz
+}
+
+// SetAPIKeys 允许邮箱所有者及所在组织成员使用 API Key（X-API-Key）代替邮箱Token（需先 SetUserAccess）
+func (ma *MailboxAuth) SetAPIKeys(apiKeys *service.APIKeyService) {
+	ma.apiKeys = apiKeys
+}
+
+// SetRequestQuota 以 JWT、API Key 访问邮箱时按用户等级计入每分钟请求配额（邮箱令牌访问不计入）
+func (ma *MailboxAuth) SetRequestQuota(quota *RequestQuota) {
+	ma.quota = quota
+}
+
+// apiKeyAllowed 判断 API Key 所属用户是否有权访问邮箱
+func (ma *MailboxAuth) apiKeyAllowed(c *gin.Context, apiKey string, mailbox *domain.Mailbox) bool {
+	if ma.apiKeys == nil || ma.authz == nil || apiKey == "" {
+		return false
+	}
+	user, err := ma.apiKeys.ValidateAPIKey(c.Request.Context(), apiKey)
+	if err != nil || !ma.authz.CanAccessMailbox(c.Request.Context(), user.ID, mailbox, service.ActionWrite) {
+		return false
+	}
+	c.Set("userID", user.ID)
+	c.Set("email", user.Email)
+	c.Set("tier", string(user.Tier))
+	return true
+}
+
+// userAllowed 判断 JWT 用户是否有权访问邮箱（按实时组织成员关系判断）
+func (ma *MailboxAuth) userAllowed(c *gin.Context, token string, mailbox *domain.Mailbox) bool {
+	if ma.jwtManager == nil || ma.authz == nil {
+		return false
+	}
+	claims, err := ma.jwtManager.Authenticate(c.Request.Context(), token)
+	if err != nil {
+		return false
+	}
+	// 代登录令牌只读
+	if claims.Impersonator != "" && !readOnlyMethod(c.Request.Method) {
+		return false
+	}
+	if !ma.authz.CanAccessMailbox(c.Request.Context(), claims.UserID, mailbox, service.ActionWrite) {
+		return false
+	}
+	c.Set("userID", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("tier", claims.Tier)
+	return true
+}
+
 // RequireMailboxToken 要求邮箱Token验证
 func (ma *MailboxAuth) RequireMailboxToken() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -38,7 +97,8 @@ func (ma *MailboxAuth) RequireMailboxToken() gin.HandlerFunc {
 
 		// 从多个来源提取Token
 		token := ma.extractToken(c)
-		if token == "" {
+		apiKey := c.GetHeader("X-API-Key")
+		if token == "" && apiKey == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "mailbox token required",
 			})
@@ -47,7 +107,7 @@ func (ma *MailboxAuth) RequireMailboxToken() gin.HandlerFunc {
 		}
 
 		// 获取邮箱并验证Token
-		mailbox, err := ma.mailboxService.Get(mailboxID)
+		mailbox, err := ma.mailboxService.Get(c.Request.Context(), mailboxID)
 		if err != nil {
 			ma.log.Warn("mailbox not found",
 				zap.String("mailbox_id", mailboxID),
@@ -60,8 +120,9 @@ func (ma *MailboxAuth) RequireMailboxToken() gin.HandlerFunc {
 			return
 		}
 
-		// 验证Token
-		if mailbox.Token != token {
+		// 验证Token（或有权限用户的 JWT、API Key）
+		allowed := token != "" && (mailbox.Token == token || ma.userAllowed(c, token, mailbox))
+		if !allowed && !(token == "" && ma.apiKeyAllowed(c, apiKey, mailbox)) {
 			ma.log.Warn("invalid mailbox token",
 				zap.String("mailbox_id", mailboxID),
 				zap.String("ip", c.ClientIP()),
@@ -73,6 +134,19 @@ func (ma *MailboxAuth) RequireMailboxToken() gin.HandlerFunc {
 			return
 		}
 
+		// 因滥用被停用的邮箱拒绝一切访问（包括所有者）
+		if mailbox.Suspended {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "mailbox suspended",
+			})
+			c.Abort()
+			return
+		}
+
+		if !ma.quota.allow(c) {
+			return
+		}
+
 		// 将邮箱信息存储到上下文中
 		c.Set("mailbox", mailbox)
 		c.Next()
@@ -119,8 +193,8 @@ func (ma *MailboxAuth) OptionalMailboxToken() gin.HandlerFunc {
 
 		// 如果提供了Token，则必须验证通过
 		if mailboxID != "" {
-			mailbox, err := ma.mailboxService.Get(mailboxID)
-			if err == nil && mailbox.Token == token {
+			mailbox, err := ma.mailboxService.Get(c.Request.Context(), mailboxID)
+			if err == nil && !mailbox.Suspended && (mailbox.Token == token || ma.userAllowed(c, token, mailbox)) {
 				c.Set("mailbox", mailbox)
 				c.Set("authenticated", true)
 			}
@@ -129,3 +203,25 @@ func (ma *MailboxAuth) OptionalMailboxToken() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireWritable 要求邮箱可写（需在 RequireMailboxToken 之后使用）
+//
+// 所属用户域名过了宽限期后邮箱冻结为只读，拒绝所有修改操作。
+func (ma *MailboxAuth) RequireWritable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("mailbox")
+		if !ok {
+			c.Next()
+			return
+		}
+		mailbox, _ := value.(*domain.Mailbox)
+		if err := ma.mailboxService.CheckWritable(c.Request.Context(), mailbox); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "mailbox is read-only: domain expired",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceRetryAfter 维护模式下建议客户端重试的间隔（秒）
+const MaintenanceRetryAfter = 120
+
+// MaintenanceChecker 维护模式状态提供者
+type MaintenanceChecker interface {
+	MaintenanceState() (readOnly bool, message string)
+}
+
+// maintenanceAllowlist 只读模式下仍允许写请求的路径
+var maintenanceAllowlist = map[string]bool{
+	"/v1/admin/maintenance": true, // 维护开关本身
+	"/v1/auth/login":        true,
+	"/v1/auth/refresh":      true,
+	"/v1/auth/2fa/login":    true,
+	"/v1/auth/logout":       true,
+}
+
+// ReadOnlyMode 只读维护模式中间件
+//
+// 维护模式开启时，所有写方法（POST/PUT/PATCH/DELETE）返回 503 并附带 Retry-After，
+// 读请求、健康检查、指标以及白名单内的端点不受影响。
+func ReadOnlyMode(checker MaintenanceChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if checker == nil || !isMutatingMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		readOnly, message := checker.MaintenanceState()
+		if !readOnly || isMaintenanceExempt(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		if message == "" {
+			message = "系统维护中，暂时只读，请稍后重试"
+		}
+
+		RespondThrottled(c, Throttle{
+			Status:     http.StatusServiceUnavailable,
+			ErrorCode:  ErrorCodeMaintenance,
+			Message:    message,
+			RetryAfter: MaintenanceRetryAfter * time.Second,
+			Data: gin.H{
+				"maintenance": true,
+				"readOnly":    true,
+			},
+		})
+	}
+}
+
+// isMutatingMethod 判断是否为写方法
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isMaintenanceExempt 判断路径是否不受维护模式限制
+func isMaintenanceExempt(path string) bool {
+	if maintenanceAllowlist[path] {
+		return true
+	}
+	return strings.HasPrefix(path, "/health") || path == "/metrics"
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/domain"
+)
+
+// requestQuotaWindow 用户请求配额的计数窗口（MaxAPIRequestsPerMinute）
+const requestQuotaWindow = time.Minute
+
+// requestQuotaCounted 上下文键：本次请求已计入用户请求配额
+const requestQuotaCounted = "requestQuotaCounted"
+
+// RequestCounter 请求计数（复用限流计数器，hybrid 存储下计数在 Redis 中，多实例共享）
+type RequestCounter interface {
+	IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error)
+}
+
+// RequestQuota 按用户等级限制每分钟 API 请求数
+//
+// 由认证中间件在识别出用户后调用（JWT、API Key 或所有者访问邮箱），游客和邮箱令牌访问不计入。
+// 计数按自然分钟分桶，X-RateLimit-Reset 为下一分钟开始；计数器不可用时放行。
+type RequestQuota struct {
+	counter RequestCounter
+	now     func() time.Time
+	log     *zap.Logger
+}
+
+// NewRequestQuota 创建用户请求配额
+func NewRequestQuota(counter RequestCounter, log *zap.Logger) *RequestQuota {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	return &RequestQuota{counter: counter, now: time.Now, log: log}
+}
+
+// allow 计入一次请求并输出 X-RateLimit-*，超出等级上限时输出 429 并返回 false
+//
+// 未识别出用户或同一请求已经计入时直接放行；nil 表示未启用。
+func (q *RequestQuota) allow(c *gin.Context) bool {
+	userID := c.GetString("userID")
+	if q == nil || userID == "" || c.GetBool(requestQuotaCounted) {
+		return true
+	}
+	c.Set(requestQuotaCounted, true)
+
+	window, ok := q.Take(c.Request.Context(), userID, domain.UserTier(c.GetString("tier")))
+	if window == nil {
+		return true
+	}
+	SetRateLimitHeaders(c, *window)
+	if !ok {
+		RespondThrottled(c, Throttle{
+			Status:     http.StatusTooManyRequests,
+			ErrorCode:  ErrorCodeRateLimited,
+			Message:    "请求过于频繁，已超出账户等级的每分钟请求配额",
+			RetryAfter: window.Reset.Sub(q.now()),
+			Window:     window,
+		})
+		return false
+	}
+	return true
+}
+
+// Take 计入用户的一次请求，返回本分钟的计数窗口和是否仍在等级配额内
+//
+// 不限次数的等级和计数器不可用时窗口为 nil 并放行。供 gin 以外的入口（gRPC）使用。
+func (q *RequestQuota) Take(ctx context.Context, userID string, tier domain.UserTier) (*QuotaWindow, bool) {
+	limit := domain.DefaultQuotas(tier).MaxAPIRequestsPerMinute
+	if limit < 0 {
+		return nil, true
+	}
+	bucket := q.now().Truncate(requestQuotaWindow)
+	count, err := q.counter.IncrementRateLimit(ctx, fmt.Sprintf("quota:api:%s:%d", userID, bucket.Unix()), requestQuotaWindow)
+	if err != nil {
+		q.log.Warn("request quota counter unavailable", zap.String("user_id", userID), zap.Error(err))
+		return nil, true
+	}
+	window := &QuotaWindow{Limit: limit, Remaining: limit - int(count), Reset: bucket.Add(requestQuotaWindow)}
+	return window, int(count) <= limit
+}
+
+// QuotaUsage 按数量计算的配额（邮箱数、邮件数），输出为 X-Quota-* 响应头
+type QuotaUsage struct {
+	Scope string // 配额范围，如 user.mailboxes、mailbox.messages
+	Limit int
+	Used  int
+}
+
+// SetQuotaHeaders 输出数量配额的 X-Quota-* 响应头
+func SetQuotaHeaders(c *gin.Context, usage QuotaUsage) {
+	c.Header("X-Quota-Scope", usage.Scope)
+	c.Header("X-Quota-Limit", strconv.Itoa(usage.Limit))
+	c.Header("X-Quota-Used", strconv.Itoa(usage.Used))
+}
+
+// RespondQuotaExceeded 输出 403 数量配额用尽响应并中止处理链
+//
+// 数量配额在删除或过期后才释放，没有固定的恢复时间，因此不带 Retry-After；
+// 原生 API 的 data 带 errorCode、scope、limit、used，兼容 API（/api/）为 {"error","errorCode"}。
+func RespondQuotaExceeded(c *gin.Context, usage QuotaUsage, message string) {
+	SetQuotaHeaders(c, usage)
+
+	if strings.HasPrefix(c.Request.URL.Path, compatPathPrefix) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":     message,
+			"errorCode": ErrorCodeQuotaExceeded,
+		})
+		return
+	}
+
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"code": http.StatusForbidden,
+		"msg":  message,
+		"data": gin.H{
+			"errorCode": ErrorCodeQuotaExceeded,
+			"scope":     usage.Scope,
+			"limit":     usage.Limit,
+			"used":      usage.Used,
+		},
+	})
+}
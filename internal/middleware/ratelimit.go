@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ipWindow 单个 IP 的固定窗口计数
+type ipWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+// IPLimiter 进程内按 IP 的固定窗口限流器，上限可在运行时修改
+type IPLimiter struct {
+	window time.Duration
+	limit  atomic.Int64 // 每个窗口的请求上限，0 表示不限流
+
+	mu        sync.Mutex
+	windows   map[string]*ipWindow
+	lastSweep time.Time
+}
+
+// NewIPLimiter 创建按 IP 的限流器，limit 为 0 时不限流
+func NewIPLimiter(limit int, window time.Duration) *IPLimiter {
+	l := &IPLimiter{
+		window:    window,
+		windows:   make(map[string]*ipWindow),
+		lastSweep: time.Now(),
+	}
+	l.SetLimit(limit)
+	return l
+}
+
+// SetLimit 修改每个窗口的请求上限，0 表示不限流；已有窗口的计数保留
+func (l *IPLimiter) SetLimit(limit int) {
+	l.limit.Store(int64(max(limit, 0)))
+}
+
+// Limit 当前每个窗口的请求上限
+func (l *IPLimiter) Limit() int {
+	return int(l.limit.Load())
+}
+
+// Middleware 返回限流中间件，超出限制返回 429 并附带 Retry-After
+func (l *IPLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := l.Limit()
+		if limit == 0 {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		count, resetAt := l.hit(c.ClientIP(), now)
+
+		window := QuotaWindow{Limit: limit, Remaining: limit - count, Reset: resetAt}
+		SetRateLimitHeaders(c, window)
+
+		if count > limit {
+			RespondThrottled(c, Throttle{
+				Status:     http.StatusTooManyRequests,
+				ErrorCode:  ErrorCodeRateLimited,
+				Message:    "请求过于频繁，请稍后重试",
+				RetryAfter: time.Until(resetAt),
+				Window:     &window,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// hit 计入一次请求，返回窗口内的请求数和窗口重置时间
+func (l *IPLimiter) hit(ip string, now time.Time) (int, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 定期清理过期窗口，避免 map 无限增长
+	if now.Sub(l.lastSweep) > l.window {
+		for key, w := range l.windows {
+			if now.After(w.resetAt) {
+				delete(l.windows, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.windows[ip]
+	if !ok || now.After(w.resetAt) {
+		w = &ipWindow{resetAt: now.Add(l.window)}
+		l.windows[ip] = w
+	}
+	w.count++
+	return w.count, w.resetAt
+}
+
+// IPRateLimit 进程内按 IP 的固定窗口限流中间件
+//
+// 用于无需认证的公开端点，超出限制返回 429 并附带 Retry-After。
+func IPRateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	return NewIPLimiter(limit, window).Middleware()
+}
+
+// RuntimeRateLimits 系统配置 rateLimit 节控制的全局限流，管理员修改后即时生效
+//
+// API 限流按 IP 每分钟计数，窗口内允许 RequestsPerMinute + BurstSize 次请求；
+// 创建邮箱按 IP 每小时计数。关闭限流时两者都放行。
+type RuntimeRateLimits struct {
+	API           *IPLimiter
+	CreateMailbox *IPLimiter
+}
+
+// NewRuntimeRateLimits 创建全局限流，应用系统配置前不限流
+func NewRuntimeRateLimits() *RuntimeRateLimits {
+	return &RuntimeRateLimits{
+		API:           NewIPLimiter(0, time.Minute),
+		CreateMailbox: NewIPLimiter(0, time.Hour),
+	}
+}
+
+// ApplySystemConfig 应用系统配置中的限流设置（注册为 ConfigService 的运行时回调）
+func (r *RuntimeRateLimits) ApplySystemConfig(config *domain.SystemConfig) {
+	if !config.RateLimit.Enabled {
+		r.API.SetLimit(0)
+		r.CreateMailbox.SetLimit(0)
+		return
+	}
+	r.API.SetLimit(config.RateLimit.RequestsPerMinute + max(config.RateLimit.BurstSize, 0))
+	r.CreateMailbox.SetLimit(config.RateLimit.CreateMailboxLimit)
+}
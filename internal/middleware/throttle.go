@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 限流或暂时不可用时响应体 data.errorCode 的取值
+const (
+	ErrorCodeRateLimited   = "RATE_LIMITED"   // 请求频率超限
+	ErrorCodeQuotaExceeded = "QUOTA_EXCEEDED" // 配额用尽
+	ErrorCodeMaintenance   = "MAINTENANCE"    // 维护模式
+	ErrorCodeTryLater      = "TRY_LATER"      // 其他临时不可用（账户临时锁定、下游暂时失败等）
+)
+
+// compatPathPrefix 兼容 API 的路径前缀，错误沿用上游格式 {"error": "..."}
+const compatPathPrefix = "/api/"
+
+// QuotaWindow 配额窗口，输出为 X-RateLimit-* 响应头
+type QuotaWindow struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// Throttle 限流或暂时不可用的拒绝响应
+type Throttle struct {
+	Status     int           // 429、423 或 503
+	ErrorCode  string        // ErrorCodeXxx
+	Message    string        // 面向用户的提示
+	RetryAfter time.Duration // 建议的重试间隔，向上取整到秒，至少 1 秒
+	Window     *QuotaWindow  // 适用配额窗口时输出 X-RateLimit-*（可选）
+	Data       gin.H         // 附加到 data 的字段（可选）
+}
+
+// RetryAfterSeconds Retry-After 的秒数（向上取整，至少 1 秒）
+func RetryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
+
+// SetRateLimitHeaders 输出配额窗口的 X-RateLimit-* 响应头
+func SetRateLimitHeaders(c *gin.Context, window QuotaWindow) {
+	c.Header("X-RateLimit-Limit", strconv.Itoa(window.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(max(window.Remaining, 0)))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(window.Reset.Unix(), 10))
+}
+
+// RespondThrottled 输出限流或暂时不可用的拒绝响应并中止处理链
+//
+// 所有限流、配额、维护和登录锁定的拒绝都经由这里，保证 Retry-After 头与响应体中的
+// retryAfterSeconds 一致：原生 API 为 {"code","msg","data":{"errorCode","retryAfterSeconds",...}}，
+// 兼容 API（/api/）为 {"error","errorCode","retryAfterSeconds"}。
+func RespondThrottled(c *gin.Context, t Throttle) {
+	seconds := RetryAfterSeconds(t.RetryAfter)
+	if t.Window != nil {
+		SetRateLimitHeaders(c, *t.Window)
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+
+	if strings.HasPrefix(c.Request.URL.Path, compatPathPrefix) {
+		c.AbortWithStatusJSON(t.Status, gin.H{
+			"error":             t.Message,
+			"errorCode":         t.ErrorCode,
+			"retryAfterSeconds": seconds,
+		})
+		return
+	}
+
+	data := gin.H{}
+	for key, value := range t.Data {
+		data[key] = value
+	}
+	data["errorCode"] = t.ErrorCode
+	data["retryAfterSeconds"] = seconds
+	c.AbortWithStatusJSON(t.Status, gin.H{
+		"code": t.Status,
+		"msg":  t.Message,
+		"data": data,
+	})
+}
@@ -60,7 +60,7 @@ func NewHealthChecker(store storage.Store, logger *zap.Logger, version, env stri
 }
 
 // CheckHealth 执行健康检查
-func (hc *HealthChecker) CheckHealth() *HealthReport {
+func (hc *HealthChecker) CheckHealth(ctx context.Context) *HealthReport {
 	report := &HealthReport{
 		Timestamp:   time.Now(),
 		Uptime:      time.Since(hc.startTime),
@@ -72,7 +72,7 @@ func (hc *HealthChecker) CheckHealth() *HealthReport {
 	// 执行各项健康检查
 	checks := []func() HealthCheck{
 		hc.checkDatabase,
-		hc.checkRedis,
+		func() HealthCheck { return hc.checkRedis(ctx) },
 		hc.checkMemory,
 		hc.checkCPU,
 		hc.checkStorage,
@@ -123,7 +123,7 @@ func (hc *HealthChecker) checkDatabase() HealthCheck {
 }
 
 // checkRedis 检查 Redis 连接
-func (hc *HealthChecker) checkRedis() HealthCheck {
+func (hc *HealthChecker) checkRedis(ctx context.Context) HealthCheck {
 	start := time.Now()
 
 	check := HealthCheck{
@@ -133,7 +133,7 @@ func (hc *HealthChecker) checkRedis() HealthCheck {
 
 	// 检查 Redis 连接
 	if rateLimitStore, ok := hc.store.(storage.RateLimitRepository); ok {
-		_, err := rateLimitStore.GetRateLimit("health_check")
+		_, err := rateLimitStore.GetRateLimit(ctx, "health_check")
 		if err != nil {
 			check.Status = HealthStatusDegraded
 			check.Message = fmt.Sprintf("Redis connection issue: %v", err)
@@ -252,7 +252,7 @@ func (hc *HealthChecker) checkSystem() HealthCheck {
 
 // IsHealthy 检查系统是否健康
 func (hc *HealthChecker) IsHealthy() bool {
-	report := hc.CheckHealth()
+	report := hc.CheckHealth(context.Background())
 	return report.Status == HealthStatusHealthy
 }
 
@@ -271,7 +271,7 @@ func (hc *HealthChecker) StartPeriodicHealthCheck(ctx context.Context, interval
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			report := hc.CheckHealth()
+			report := hc.CheckHealth(ctx)
 
 			// 记录健康状态
 			if report.Status == HealthStatusUnhealthy {
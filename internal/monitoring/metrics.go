@@ -2,6 +2,7 @@ package monitoring
 
 import (
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -49,6 +50,38 @@ type Metrics struct {
 	RateLimitHits   *prometheus.CounterVec
 	RateLimitBlocks *prometheus.CounterVec
 
+	// 缓存指标
+	CacheCoalescedQueries *prometheus.CounterVec
+	CacheNegativeHits     *prometheus.CounterVec
+	CacheLookups          *prometheus.CounterVec
+	CacheHitRatio         *prometheus.GaugeVec
+
+	// cacheLookups 各操作累计的缓存命中与查询次数（用于计算命中率）
+	cacheLookupsMu sync.Mutex
+	cacheLookups   map[string]*cacheLookupCount
+
+	// 垃圾邮件评分指标
+	SpamChecks *prometheus.CounterVec
+
+	// 附件病毒扫描指标
+	AttachmentScans *prometheus.CounterVec
+
+	// SMTP 指标
+	SMTPRecipientsPerTransaction prometheus.Histogram
+	SMTPRecipientsDeferred       prometheus.Counter
+	SMTPSessionsActive           prometheus.Gauge
+	SMTPSessionsByState          *prometheus.GaugeVec
+	SMTPSessionDuration          prometheus.Histogram
+	SMTPRejections               *prometheus.CounterVec
+
+	// Webhook 熔断指标
+	WebhookBreakerState   *prometheus.GaugeVec
+	WebhookShortCircuited *prometheus.CounterVec
+
+	// WebSocket 发送队列指标
+	WebSocketEventsDropped  prometheus.Counter
+	WebSocketClientsEvicted prometheus.Counter
+
 	// 业务指标
 	DomainUsage         *prometheus.GaugeVec
 	AttachmentSize      *prometheus.HistogramVec
@@ -243,6 +276,130 @@ func NewMetrics() *Metrics {
 			[]string{"type", "key"},
 		),
 
+		// 缓存指标
+		CacheCoalescedQueries: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tempmail_cache_coalesced_queries_total",
+				Help: "Total number of cache-miss queries coalesced into an in-flight lookup",
+			},
+			[]string{"operation"},
+		),
+
+		CacheNegativeHits: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tempmail_cache_negative_hits_total",
+				Help: "Total number of lookups answered by the not-found cache",
+			},
+			[]string{"operation"},
+		),
+
+		CacheLookups: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tempmail_cache_lookups_total",
+				Help: "Total number of cache lookups by operation and result (hit, miss)",
+			},
+			[]string{"operation", "result"},
+		),
+
+		CacheHitRatio: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tempmail_cache_hit_ratio",
+				Help: "Cache hit ratio since startup by operation (0-1)",
+			},
+			[]string{"operation"},
+		),
+		cacheLookups: make(map[string]*cacheLookupCount),
+
+		// 垃圾邮件评分指标
+		SpamChecks: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tempmail_spam_checks_total",
+				Help: "Total number of spam checks by outcome (deliver, quarantine, reject, unavailable)",
+			},
+			[]string{"outcome"},
+		),
+
+		// 附件病毒扫描指标
+		AttachmentScans: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tempmail_attachment_scans_total",
+				Help: "Total number of scanned messages with attachments by outcome (clean, infected, unavailable)",
+			},
+			[]string{"outcome"},
+		),
+
+		// SMTP 指标
+		SMTPRecipientsPerTransaction: promauto.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "tempmail_smtp_recipients_per_transaction",
+				Help:    "Number of accepted recipients per SMTP transaction",
+				Buckets: []float64{1, 2, 3, 5, 10, 15, 20, 25, 50},
+			},
+		),
+		SMTPRecipientsDeferred: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Name: "tempmail_smtp_recipients_deferred_total",
+				Help: "Total number of recipients deferred with 452 because the per-transaction cap was reached",
+			},
+		),
+		SMTPRejections: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tempmail_smtp_rejections_total",
+				Help: "Total number of SMTP commands rejected by reason (ip_connections, sender_rate, greylisted, relay_denied, unknown_recipient, spam, auth)",
+			},
+			[]string{"reason"},
+		),
+		SMTPSessionsActive: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "tempmail_smtp_sessions_active",
+				Help: "Number of open SMTP sessions",
+			},
+		),
+		SMTPSessionsByState: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tempmail_smtp_sessions_by_state",
+				Help: "Number of open SMTP sessions by state (connected, mail, rcpt, data)",
+			},
+			[]string{"state"},
+		),
+		SMTPSessionDuration: promauto.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "tempmail_smtp_session_duration_seconds",
+				Help:    "SMTP session duration in seconds, observed when the session closes",
+				Buckets: []float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 900},
+			},
+		),
+
+		// Webhook 熔断指标
+		WebhookBreakerState: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tempmail_webhook_breaker_state",
+				Help: "Webhook circuit breaker state per endpoint host (0=closed, 1=half_open, 2=open)",
+			},
+			[]string{"host"},
+		),
+		WebhookShortCircuited: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tempmail_webhook_short_circuited_total",
+				Help: "Total number of webhook deliveries skipped because the endpoint host circuit was open",
+			},
+			[]string{"host"},
+		),
+
+		// WebSocket 发送队列指标
+		WebSocketEventsDropped: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Name: "tempmail_websocket_events_dropped_total",
+				Help: "Total number of WebSocket messages dropped because the client's send queue was full",
+			},
+		),
+		WebSocketClientsEvicted: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Name: "tempmail_websocket_clients_evicted_total",
+				Help: "Total number of WebSocket clients disconnected with close code 4006 for reading too slowly",
+			},
+		),
+
 		// 业务指标
 		DomainUsage: promauto.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -335,6 +492,117 @@ func (m *Metrics) RecordRateLimitBlock(limitType, key string) {
 	m.RateLimitBlocks.WithLabelValues(limitType, key).Inc()
 }
 
+// RecordCacheCoalesced 记录被合并的缓存未命中查询
+func (m *Metrics) RecordCacheCoalesced(operation string) {
+	m.CacheCoalescedQueries.WithLabelValues(operation).Inc()
+}
+
+// RecordCacheNegativeHit 记录负缓存命中
+func (m *Metrics) RecordCacheNegativeHit(operation string) {
+	m.CacheNegativeHits.WithLabelValues(operation).Inc()
+}
+
+// cacheLookupCount 单个操作的缓存查询计数
+type cacheLookupCount struct {
+	hits  uint64
+	total uint64
+}
+
+// RecordCacheLookup 记录一次缓存查询结果，并更新该操作自启动以来的命中率
+func (m *Metrics) RecordCacheLookup(operation string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.CacheLookups.WithLabelValues(operation, result).Inc()
+
+	m.cacheLookupsMu.Lock()
+	count, ok := m.cacheLookups[operation]
+	if !ok {
+		count = &cacheLookupCount{}
+		m.cacheLookups[operation] = count
+	}
+	count.total++
+	if hit {
+		count.hits++
+	}
+	m.CacheHitRatio.WithLabelValues(operation).Set(float64(count.hits) / float64(count.total))
+	m.cacheLookupsMu.Unlock()
+}
+
+// RecordSpamCheck 记录垃圾邮件评分结果
+func (m *Metrics) RecordSpamCheck(outcome string) {
+	m.SpamChecks.WithLabelValues(outcome).Inc()
+}
+
+// RecordAttachmentScan 记录附件病毒扫描结果
+func (m *Metrics) RecordAttachmentScan(outcome string) {
+	m.AttachmentScans.WithLabelValues(outcome).Inc()
+}
+
+// RecordRecipientsPerTransaction 记录单次 SMTP 事务接收的收件人数
+func (m *Metrics) RecordRecipientsPerTransaction(count int) {
+	m.SMTPRecipientsPerTransaction.Observe(float64(count))
+}
+
+// RecordRecipientDeferred 记录因超出单次事务上限而延后的收件人
+func (m *Metrics) RecordRecipientDeferred() {
+	m.SMTPRecipientsDeferred.Inc()
+}
+
+// RecordSMTPRejection 记录被收信保护拒绝的 SMTP 命令
+func (m *Metrics) RecordSMTPRejection(reason string) {
+	m.SMTPRejections.WithLabelValues(reason).Inc()
+}
+
+// RecordSMTPSessionOpened 记录新的 SMTP 会话
+func (m *Metrics) RecordSMTPSessionOpened() {
+	m.SMTPSessionsActive.Inc()
+}
+
+// RecordSMTPSessionState 记录 SMTP 会话阶段变化（from 为空表示新会话，to 为空表示会话结束）
+func (m *Metrics) RecordSMTPSessionState(from, to string) {
+	if from != "" {
+		m.SMTPSessionsByState.WithLabelValues(from).Dec()
+	}
+	if to != "" {
+		m.SMTPSessionsByState.WithLabelValues(to).Inc()
+	}
+}
+
+// RecordSMTPSessionClosed 记录 SMTP 会话结束及其持续时长
+func (m *Metrics) RecordSMTPSessionClosed(duration time.Duration) {
+	m.SMTPSessionsActive.Dec()
+	m.SMTPSessionDuration.Observe(duration.Seconds())
+}
+
+// RecordWebhookBreakerState 记录 Webhook 目标主机的熔断状态
+func (m *Metrics) RecordWebhookBreakerState(host, state string) {
+	value := 0.0
+	switch state {
+	case "half_open":
+		value = 1
+	case "open":
+		value = 2
+	}
+	m.WebhookBreakerState.WithLabelValues(host).Set(value)
+}
+
+// RecordWebhookShortCircuit 记录因熔断跳过的 Webhook 投递
+func (m *Metrics) RecordWebhookShortCircuit(host string) {
+	m.WebhookShortCircuited.WithLabelValues(host).Inc()
+}
+
+// RecordWebSocketDropped 记录因发送队列已满丢弃的 WebSocket 消息
+func (m *Metrics) RecordWebSocketDropped() {
+	m.WebSocketEventsDropped.Inc()
+}
+
+// RecordWebSocketEvicted 记录因读取过慢被断开的 WebSocket 连接
+func (m *Metrics) RecordWebSocketEvicted() {
+	m.WebSocketClientsEvicted.Inc()
+}
+
 // UpdateMailboxesActive 更新活跃邮箱数
 func (m *Metrics) UpdateMailboxesActive(count int) {
 	m.MailboxesActive.Set(float64(count))
@@ -428,6 +696,20 @@ func (m *Metrics) RegisterCustomMetrics() {
 		m.PanicsTotal,
 		m.RateLimitHits,
 		m.RateLimitBlocks,
+		m.CacheCoalescedQueries,
+		m.CacheNegativeHits,
+		m.CacheLookups,
+		m.CacheHitRatio,
+		m.SpamChecks,
+		m.AttachmentScans,
+		m.SMTPRecipientsPerTransaction,
+		m.SMTPRecipientsDeferred,
+		m.SMTPRejections,
+		m.SMTPSessionsActive,
+		m.SMTPSessionsByState,
+		m.SMTPSessionDuration,
+		m.WebhookBreakerState,
+		m.WebhookShortCircuited,
 		m.DomainUsage,
 		m.AttachmentSize,
 		m.EmailProcessingTime,
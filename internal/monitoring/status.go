@@ -0,0 +1,265 @@
+package monitoring
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ComponentState 对外公开的组件状态
+type ComponentState string
+
+const (
+	StateOK       ComponentState = "ok"
+	StateDegraded ComponentState = "degraded"
+	StateDown     ComponentState = "down"
+)
+
+// 对外公开的组件名
+const (
+	ComponentAPI       = "api"
+	ComponentSMTP      = "smtp"
+	ComponentWebSocket = "websocket"
+	ComponentWebhooks  = "webhooks"
+)
+
+// StatusComponents 公开组件列表（顺序固定，历史采样按此顺序编码）
+var StatusComponents = []string{ComponentAPI, ComponentSMTP, ComponentWebSocket, ComponentWebhooks}
+
+// DefaultWebhookBacklogThreshold Webhook 待重试积压达到该值时视为降级
+const DefaultWebhookBacklogThreshold = 10
+
+// HealthSource 就绪探测来源（storage.Store 满足此接口）
+type HealthSource interface {
+	Health() error
+}
+
+// BacklogSource Webhook 积压来源
+type BacklogSource interface {
+	Backlog() int
+}
+
+// StatusSignals 进程内状态信号
+//
+// 由 SMTP 服务器、WebSocket Hub 等在运行时更新，读取只涉及原子操作，采样零开销。
+type StatusSignals struct {
+	smtpListening  atomic.Bool
+	wsRunning      atomic.Bool
+	lastIngestOK   atomic.Int64 // UnixNano
+	lastIngestFail atomic.Int64 // UnixNano
+}
+
+// SetSMTPListening 设置 SMTP 监听状态
+func (s *StatusSignals) SetSMTPListening(listening bool) {
+	s.smtpListening.Store(listening)
+}
+
+// SetWebSocketRunning 设置 WebSocket Hub 运行状态
+func (s *StatusSignals) SetWebSocketRunning(running bool) {
+	s.wsRunning.Store(running)
+}
+
+// RecordIngest 记录一次邮件入库结果
+func (s *StatusSignals) RecordIngest(err error) {
+	now := time.Now().UnixNano()
+	if err != nil {
+		s.lastIngestFail.Store(now)
+		return
+	}
+	s.lastIngestOK.Store(now)
+}
+
+// StatusSnapshot 公开状态快照
+//
+// 只包含粗粒度组件状态，不暴露版本号、主机名等内部信息。
+type StatusSnapshot struct {
+	Status       ComponentState            `json:"status"`
+	Components   map[string]ComponentState `json:"components"`
+	LastIngestAt *time.Time                `json:"lastIngestAt,omitempty"`
+	CheckedAt    time.Time                 `json:"checkedAt"`
+}
+
+// StatusMonitor 公开状态监控
+//
+// Evaluate 是唯一执行探测的地方（每次一次存储健康检查），
+// 结果缓存供公开接口读取，同时写入运行时间记录器，避免重复探测。
+type StatusMonitor struct {
+	source           HealthSource
+	signals          *StatusSignals
+	recorder         *UptimeRecorder
+	backlog          BacklogSource
+	backlogThreshold int
+	logger           *zap.Logger
+
+	mu      sync.RWMutex
+	current *StatusSnapshot
+}
+
+// NewStatusMonitor 创建状态监控
+func NewStatusMonitor(source HealthSource, recorder *UptimeRecorder, logger *zap.Logger) *StatusMonitor {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &StatusMonitor{
+		source:           source,
+		signals:          &StatusSignals{},
+		recorder:         recorder,
+		backlogThreshold: DefaultWebhookBacklogThreshold,
+		logger:           logger,
+	}
+}
+
+// Signals 获取状态信号（供各组件上报）
+func (m *StatusMonitor) Signals() *StatusSignals {
+	return m.signals
+}
+
+// SetBacklogSource 设置 Webhook 积压来源
+func (m *StatusMonitor) SetBacklogSource(source BacklogSource, threshold int) {
+	m.backlog = source
+	if threshold > 0 {
+		m.backlogThreshold = threshold
+	}
+}
+
+// Evaluate 执行一次就绪评估并缓存结果
+func (m *StatusMonitor) Evaluate() StatusSnapshot {
+	now := time.Now().UTC()
+	components := make(map[string]ComponentState, len(StatusComponents))
+
+	// api：唯一的外部探测
+	apiState := StateOK
+	if m.source != nil {
+		if err := m.source.Health(); err != nil {
+			apiState = StateDown
+		}
+	}
+	components[ComponentAPI] = apiState
+
+	// smtp：监听状态 + 最近入库结果
+	smtpState := StateOK
+	lastOK := m.signals.lastIngestOK.Load()
+	switch {
+	case !m.signals.smtpListening.Load():
+		smtpState = StateDown
+	case apiState == StateDown, m.signals.lastIngestFail.Load() > lastOK:
+		smtpState = StateDegraded
+	}
+	components[ComponentSMTP] = smtpState
+
+	wsState := StateOK
+	if !m.signals.wsRunning.Load() {
+		wsState = StateDown
+	}
+	components[ComponentWebSocket] = wsState
+
+	webhookState := StateOK
+	if m.backlog != nil && m.backlog.Backlog() >= m.backlogThreshold {
+		webhookState = StateDegraded
+	}
+	components[ComponentWebhooks] = webhookState
+
+	snapshot := StatusSnapshot{
+		Status:     overallState(components),
+		Components: components,
+		CheckedAt:  now,
+	}
+	if lastOK > 0 {
+		t := time.Unix(0, lastOK).UTC()
+		snapshot.LastIngestAt = &t
+	}
+
+	m.mu.Lock()
+	m.current = &snapshot
+	m.mu.Unlock()
+
+	return snapshot
+}
+
+// Current 获取最近一次评估结果（尚未评估时立即评估）
+func (m *StatusMonitor) Current() StatusSnapshot {
+	m.mu.RLock()
+	current := m.current
+	m.mu.RUnlock()
+	if current == nil {
+		return m.Evaluate()
+	}
+	return *current
+}
+
+// History 获取运行时间历史
+func (m *StatusMonitor) History() *UptimeHistory {
+	if m.recorder == nil {
+		return &UptimeHistory{}
+	}
+	return m.recorder.History(time.Now())
+}
+
+// Run 周期性评估并采样，每 persistEvery 个采样持久化一次
+func (m *StatusMonitor) Run(ctx context.Context, interval time.Duration, persistEvery int) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if persistEvery <= 0 {
+		persistEvery = 10
+	}
+
+	if m.recorder != nil {
+		if err := m.recorder.Load(); err != nil {
+			m.logger.Warn("failed to load uptime history", zap.Error(err))
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	samples := 0
+	for {
+		snapshot := m.Evaluate()
+		if m.recorder != nil {
+			m.recorder.Record(snapshot.CheckedAt, snapshot.Components)
+			samples++
+			if samples%persistEvery == 0 {
+				if err := m.recorder.Persist(); err != nil {
+					m.logger.Warn("failed to persist uptime history", zap.Error(err))
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if m.recorder != nil {
+				if err := m.recorder.Persist(); err != nil {
+					m.logger.Warn("failed to persist uptime history", zap.Error(err))
+				}
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// overallState 计算整体状态（取最差）
+func overallState(components map[string]ComponentState) ComponentState {
+	overall := StateOK
+	for _, state := range components {
+		if stateRank(state) > stateRank(overall) {
+			overall = state
+		}
+	}
+	return overall
+}
+
+// stateRank 状态严重程度
+func stateRank(state ComponentState) int {
+	switch state {
+	case StateDown:
+		return 2
+	case StateDegraded:
+		return 1
+	}
+	return 0
+}
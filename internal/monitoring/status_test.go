@@ -0,0 +1,195 @@
+package monitoring
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flappingSource 交替返回健康/不健康的探测来源
+type flappingSource struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *flappingSource) Health() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls%2 == 0 {
+		return errors.New("database unreachable")
+	}
+	return nil
+}
+
+// memoryUptimeStore 内存持久化（模拟重启前后共享的存储）
+type memoryUptimeStore struct {
+	data []byte
+}
+
+func (m *memoryUptimeStore) SaveUptimeHistory(data []byte) error {
+	m.data = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memoryUptimeStore) LoadUptimeHistory() ([]byte, error) {
+	return m.data, nil
+}
+
+type fixedBacklog int
+
+func (b fixedBacklog) Backlog() int { return int(b) }
+
+func TestStatusMonitor_Evaluate(t *testing.T) {
+	t.Run("探测抖动时 api 在 ok 和 down 之间切换", func(t *testing.T) {
+		monitor := NewStatusMonitor(&flappingSource{}, nil, nil)
+		monitor.Signals().SetSMTPListening(true)
+		monitor.Signals().SetWebSocketRunning(true)
+
+		first := monitor.Evaluate()
+		assert.Equal(t, StateOK, first.Components[ComponentAPI])
+		assert.Equal(t, StateOK, first.Status)
+
+		second := monitor.Evaluate()
+		assert.Equal(t, StateDown, second.Components[ComponentAPI])
+		assert.Equal(t, StateDegraded, second.Components[ComponentSMTP])
+		assert.Equal(t, StateDown, second.Status)
+
+		// Current 返回缓存结果，不再触发探测
+		assert.Equal(t, second.CheckedAt, monitor.Current().CheckedAt)
+	})
+
+	t.Run("降级与宕机可区分", func(t *testing.T) {
+		monitor := NewStatusMonitor(nil, nil, nil)
+		monitor.Signals().SetSMTPListening(true)
+		monitor.Signals().SetWebSocketRunning(true)
+		monitor.SetBacklogSource(fixedBacklog(20), 10)
+
+		monitor.Signals().RecordIngest(nil)
+		monitor.Signals().RecordIngest(errors.New("disk full"))
+
+		snapshot := monitor.Evaluate()
+		assert.Equal(t, StateDegraded, snapshot.Components[ComponentSMTP])
+		assert.Equal(t, StateDegraded, snapshot.Components[ComponentWebhooks])
+		assert.Equal(t, StateOK, snapshot.Components[ComponentWebSocket])
+		assert.Equal(t, StateDegraded, snapshot.Status)
+		require.NotNil(t, snapshot.LastIngestAt)
+
+		monitor.Signals().SetWebSocketRunning(false)
+		snapshot = monitor.Evaluate()
+		assert.Equal(t, StateDown, snapshot.Components[ComponentWebSocket])
+		assert.Equal(t, StateDown, snapshot.Status)
+	})
+
+	t.Run("入库恢复后 smtp 回到 ok", func(t *testing.T) {
+		monitor := NewStatusMonitor(nil, nil, nil)
+		monitor.Signals().SetSMTPListening(true)
+		monitor.Signals().RecordIngest(errors.New("timeout"))
+		time.Sleep(time.Millisecond)
+		monitor.Signals().RecordIngest(nil)
+
+		assert.Equal(t, StateOK, monitor.Evaluate().Components[ComponentSMTP])
+	})
+}
+
+func TestUptimeRecorder_History(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 30, 0, 0, time.UTC)
+	recorder := NewUptimeRecorder(nil)
+
+	ok := map[string]ComponentState{
+		ComponentAPI: StateOK, ComponentSMTP: StateOK, ComponentWebSocket: StateOK, ComponentWebhooks: StateOK,
+	}
+	degraded := map[string]ComponentState{
+		ComponentAPI: StateOK, ComponentSMTP: StateDegraded, ComponentWebSocket: StateOK, ComponentWebhooks: StateOK,
+	}
+	down := map[string]ComponentState{
+		ComponentAPI: StateDown, ComponentSMTP: StateDegraded, ComponentWebSocket: StateOK, ComponentWebhooks: StateOK,
+	}
+
+	// 两天前的采样
+	recorder.Record(now.AddDate(0, 0, -2), down)
+	// 当前小时：3 个 ok、1 个降级、1 个宕机
+	hour := now.Truncate(time.Hour)
+	recorder.Record(hour, ok)
+	recorder.Record(hour.Add(1*time.Minute), ok)
+	recorder.Record(hour.Add(2*time.Minute), degraded)
+	recorder.Record(hour.Add(3*time.Minute), down)
+	recorder.Record(hour.Add(4*time.Minute), ok)
+	// 同一分钟重复采样覆盖前一次
+	recorder.Record(hour.Add(4*time.Minute+30*time.Second), ok)
+	// 乱序的旧采样被忽略
+	recorder.Record(hour.Add(-time.Hour), down)
+
+	history := recorder.History(now)
+	require.Len(t, history.Last24h, 24)
+	require.Len(t, history.Last7d, 7)
+
+	t.Run("按小时分桶", func(t *testing.T) {
+		current := history.Last24h[23]
+		assert.Equal(t, hour, current.Start)
+		assert.Equal(t, 5, current.Samples)
+
+		api := current.Components[ComponentAPI]
+		assert.Equal(t, 80.0, api.Uptime)
+		assert.Equal(t, StateDown, api.Status)
+		assert.Equal(t, 1, api.DownMinutes)
+
+		smtp := current.Components[ComponentSMTP]
+		assert.Equal(t, 100.0, smtp.Uptime)
+		assert.Equal(t, StateDegraded, smtp.Status)
+		assert.Equal(t, 2, smtp.DegradedMinutes)
+
+		assert.Zero(t, history.Last24h[0].Samples)
+		assert.Nil(t, history.Last24h[0].Components)
+	})
+
+	t.Run("按天分桶", func(t *testing.T) {
+		today := history.Last7d[6]
+		assert.Equal(t, time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), today.Start)
+		assert.Equal(t, 5, today.Samples)
+
+		twoDaysAgo := history.Last7d[4]
+		assert.Equal(t, 1, twoDaysAgo.Samples)
+		assert.Equal(t, 0.0, twoDaysAgo.Components[ComponentAPI].Uptime)
+	})
+}
+
+func TestUptimeRecorder_PersistAcrossRestart(t *testing.T) {
+	store := &memoryUptimeStore{}
+	now := time.Now().UTC()
+
+	before := NewUptimeRecorder(store)
+	// 超出保留期的样本在恢复时丢弃
+	before.Record(now.Add(-UptimeRetention-time.Hour), map[string]ComponentState{ComponentAPI: StateDown})
+	before.Record(now.Add(-2*time.Minute), map[string]ComponentState{ComponentAPI: StateDown})
+	before.Record(now.Add(-time.Minute), map[string]ComponentState{ComponentAPI: StateOK})
+	require.NoError(t, before.Persist())
+
+	// 模拟重启
+	after := NewUptimeRecorder(store)
+	require.NoError(t, after.Load())
+
+	samples := after.snapshot()
+	require.Len(t, samples, 2)
+	assert.Equal(t, StateDown, decodeState(samples[0].States, 0))
+	assert.Equal(t, StateOK, decodeState(samples[1].States, 0))
+
+	t.Run("监控重启后继续累积历史", func(t *testing.T) {
+		monitor := NewStatusMonitor(nil, after, nil)
+		monitor.Signals().SetSMTPListening(true)
+		monitor.Signals().SetWebSocketRunning(true)
+		snapshot := monitor.Evaluate()
+		after.Record(snapshot.CheckedAt, snapshot.Components)
+
+		history := monitor.History()
+		total := 0
+		for _, bucket := range history.Last24h {
+			total += bucket.Samples
+		}
+		assert.GreaterOrEqual(t, total, 2)
+	})
+}
@@ -0,0 +1,256 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+)
+
+// UptimeRetention 运行时间历史保留时长
+const UptimeRetention = 7 * 24 * time.Hour
+
+// uptimeCapacity 按分钟采样的环形缓冲区容量
+const uptimeCapacity = int(UptimeRetention / time.Minute)
+
+// UptimeStore 运行时间历史持久化接口
+type UptimeStore interface {
+	SaveUptimeHistory(data []byte) error
+	LoadUptimeHistory() ([]byte, error)
+}
+
+// uptimeSample 单次采样
+//
+// 状态按 StatusComponents 顺序编码为字符串（o=ok，d=degraded，x=down），持久化体积小。
+type uptimeSample struct {
+	At     int64  `json:"t"` // 分钟对齐的 Unix 秒
+	States string `json:"s"`
+}
+
+// UptimeRecorder 运行时间记录器（按分钟采样的环形缓冲区）
+type UptimeRecorder struct {
+	mu      sync.RWMutex
+	samples []uptimeSample
+	start   int // 最旧样本下标
+	count   int
+	store   UptimeStore
+}
+
+// NewUptimeRecorder 创建运行时间记录器
+//
+// store 可为 nil，此时历史只保存在内存中。
+func NewUptimeRecorder(store UptimeStore) *UptimeRecorder {
+	return &UptimeRecorder{
+		samples: make([]uptimeSample, uptimeCapacity),
+		store:   store,
+	}
+}
+
+// Record 记录一次采样（同一分钟内的重复采样覆盖前一次）
+func (r *UptimeRecorder) Record(at time.Time, components map[string]ComponentState) {
+	sample := uptimeSample{
+		At:     at.UTC().Truncate(time.Minute).Unix(),
+		States: encodeStates(components),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.push(sample)
+}
+
+// push 追加样本（调用方持有锁）
+func (r *UptimeRecorder) push(sample uptimeSample) {
+	if r.count > 0 {
+		lastIdx := (r.start + r.count - 1) % len(r.samples)
+		last := r.samples[lastIdx]
+		if sample.At == last.At {
+			r.samples[lastIdx] = sample
+			return
+		}
+		if sample.At < last.At {
+			return
+		}
+	}
+
+	if r.count < len(r.samples) {
+		r.samples[(r.start+r.count)%len(r.samples)] = sample
+		r.count++
+		return
+	}
+	r.samples[r.start] = sample
+	r.start = (r.start + 1) % len(r.samples)
+}
+
+// snapshot 按时间顺序复制全部样本
+func (r *UptimeRecorder) snapshot() []uptimeSample {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]uptimeSample, r.count)
+	for i := 0; i < r.count; i++ {
+		out[i] = r.samples[(r.start+i)%len(r.samples)]
+	}
+	return out
+}
+
+// Persist 持久化历史
+func (r *UptimeRecorder) Persist() error {
+	if r.store == nil {
+		return nil
+	}
+	data, err := json.Marshal(r.snapshot())
+	if err != nil {
+		return err
+	}
+	return r.store.SaveUptimeHistory(data)
+}
+
+// Load 从持久化存储恢复历史（丢弃超出保留期的样本）
+func (r *UptimeRecorder) Load() error {
+	if r.store == nil {
+		return nil
+	}
+	data, err := r.store.LoadUptimeHistory()
+	if err != nil || len(data) == 0 {
+		return err
+	}
+
+	var samples []uptimeSample
+	if err := json.Unmarshal(data, &samples); err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-UptimeRetention).Unix()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.start, r.count = 0, 0
+	for _, sample := range samples {
+		if sample.At >= cutoff {
+			r.push(sample)
+		}
+	}
+	return nil
+}
+
+// ComponentUptime 单个组件在时间段内的运行情况
+type ComponentUptime struct {
+	Uptime          float64        `json:"uptime"` // 非 down 采样占比（百分比）
+	Status          ComponentState `json:"status"` // 时间段内最差状态
+	DegradedMinutes int            `json:"degradedMinutes"`
+	DownMinutes     int            `json:"downMinutes"`
+}
+
+// UptimeBucket 时间段统计
+type UptimeBucket struct {
+	Start      time.Time                  `json:"start"`
+	Samples    int                        `json:"samples"`
+	Components map[string]ComponentUptime `json:"components,omitempty"`
+}
+
+// UptimeHistory 运行时间历史
+type UptimeHistory struct {
+	Last24h []UptimeBucket `json:"last24h"` // 按小时
+	Last7d  []UptimeBucket `json:"last7d"`  // 按天（UTC）
+}
+
+// History 汇总最近 24 小时（按小时）和 7 天（按天）的运行情况
+func (r *UptimeRecorder) History(now time.Time) *UptimeHistory {
+	samples := r.snapshot()
+	now = now.UTC()
+
+	hourStart := now.Truncate(time.Hour).Add(-23 * time.Hour)
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -6)
+
+	return &UptimeHistory{
+		Last24h: bucketize(samples, hourStart, time.Hour, 24),
+		Last7d:  bucketize(samples, dayStart, 24*time.Hour, 7),
+	}
+}
+
+// bucketize 将样本按固定宽度分桶统计
+func bucketize(samples []uptimeSample, start time.Time, width time.Duration, n int) []UptimeBucket {
+	type counter struct{ total, degraded, down int }
+
+	buckets := make([]UptimeBucket, n)
+	counters := make([][]counter, n)
+	for i := range buckets {
+		buckets[i].Start = start.Add(time.Duration(i) * width)
+		counters[i] = make([]counter, len(StatusComponents))
+	}
+
+	for _, sample := range samples {
+		at := time.Unix(sample.At, 0).UTC()
+		if at.Before(start) {
+			continue
+		}
+		idx := int(at.Sub(start) / width)
+		if idx >= n {
+			continue
+		}
+		buckets[idx].Samples++
+		for c := range StatusComponents {
+			counters[idx][c].total++
+			switch decodeState(sample.States, c) {
+			case StateDegraded:
+				counters[idx][c].degraded++
+			case StateDown:
+				counters[idx][c].down++
+			}
+		}
+	}
+
+	for i := range buckets {
+		if buckets[i].Samples == 0 {
+			continue
+		}
+		buckets[i].Components = make(map[string]ComponentUptime, len(StatusComponents))
+		for c, name := range StatusComponents {
+			cnt := counters[i][c]
+			status := StateOK
+			if cnt.down > 0 {
+				status = StateDown
+			} else if cnt.degraded > 0 {
+				status = StateDegraded
+			}
+			uptime := float64(cnt.total-cnt.down) / float64(cnt.total) * 100
+			buckets[i].Components[name] = ComponentUptime{
+				Uptime:          math.Round(uptime*100) / 100,
+				Status:          status,
+				DegradedMinutes: cnt.degraded,
+				DownMinutes:     cnt.down,
+			}
+		}
+	}
+
+	return buckets
+}
+
+// encodeStates 按 StatusComponents 顺序编码组件状态
+func encodeStates(components map[string]ComponentState) string {
+	buf := make([]byte, len(StatusComponents))
+	for i, name := range StatusComponents {
+		switch components[name] {
+		case StateDown:
+			buf[i] = 'x'
+		case StateDegraded:
+			buf[i] = 'd'
+		default:
+			buf[i] = 'o'
+		}
+	}
+	return string(buf)
+}
+
+// decodeState 解码指定下标的组件状态
+func decodeState(states string, idx int) ComponentState {
+	if idx >= len(states) {
+		return StateOK
+	}
+	switch states[idx] {
+	case 'x':
+		return StateDown
+	case 'd':
+		return StateDegraded
+	}
+	return StateOK
+}
@@ -0,0 +1,398 @@
+// Package otp 从邮件正文中提取一次性验证码和验证链接。
+//
+// 验证码只在验证相关关键词（code、OTP、verification、验证码等）附近查找：关键词之后最近的
+// 4–8 位数字（或 123-456 这样分组的数字）可信度最高，含数字的大写字母数字串要求紧邻关键词。
+// 年份、金额、订单号、电话号码片段以及 URL 和邮箱地址中的数字不作为候选。
+// 链接取地址或链接文字中带验证、激活、登录、重置等字样的 http(s) 链接，退订链接除外。
+package otp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/htmltext"
+)
+
+// 返回的候选验证码和链接数上限
+const (
+	MaxCodes = 5
+	MaxLinks = 5
+)
+
+// 关键词前后查找验证码的范围（字节）
+const (
+	windowBefore = 120
+	windowAfter  = 60
+	windowAlnum  = 30 // 字母数字混合的验证码只在更近的范围内查找
+)
+
+// keyword 验证码关键词；whole 为 true 时要求整词匹配（否则只要求前面不是字母）
+type keyword struct {
+	word  string
+	whole bool
+}
+
+var codeKeywords = []keyword{
+	{word: "code"}, {word: "passcode"}, {word: "verif"}, {word: "one-time"}, {word: "one time"},
+	{word: "otp", whole: true}, {word: "pin", whole: true}, {word: "kod", whole: true},
+	{word: "código"}, {word: "codigo"}, {word: "код"},
+	{word: "验证码"}, {word: "校验码"}, {word: "动态码"}, {word: "确认码"}, {word: "激活码"},
+	{word: "安全码"}, {word: "认证码"}, {word: "口令"}, {word: "コード"}, {word: "認証"}, {word: "인증"},
+}
+
+// otherCodes 这些词之后的 code 不是验证码（邮编、优惠码等）
+var otherCodes = []string{
+	"zip ", "postal ", "post ", "promo ", "promotion ", "coupon ", "discount ", "voucher ", "gift ",
+	"referral ", "invite ", "area ", "country ", "source ", "qr ", "error ", "status ", "tracking ",
+}
+
+// linkKeywords 验证链接的地址或文字中包含的字样（小写）
+var linkKeywords = []string{
+	"verif", "confirm", "activat", "validat", "magic", "login", "log-in", "signin", "sign-in", "sign_in",
+	"reset", "token", "otp", "验证", "确认", "激活", "登录", "重置",
+}
+
+// linkExcludes 不作为验证链接的地址字样
+var linkExcludes = []string{"unsubscribe", "optout", "opt-out", "preferences"}
+
+var (
+	tokenPattern = regexp.MustCompile(`[0-9A-Za-z]+`)
+	urlPattern   = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'` + "`" + `]+`)
+)
+
+// Extract 从主题和正文中提取验证码和验证链接，都没有时返回 nil
+//
+// text 为空时由 html 生成纯文本；html 中的链接按 <a> 的地址和文字判断。
+func Extract(subject, text, htmlBody string) *domain.Verification {
+	if strings.TrimSpace(text) == "" && htmlBody != "" {
+		text = htmltext.Render(htmlBody)
+	}
+	v := &domain.Verification{
+		Codes: Codes(subject + "\n\n" + text),
+		Links: Links(text, htmlBody),
+	}
+	if len(v.Codes) > 0 {
+		v.Code = v.Codes[0]
+	}
+	if v.Empty() {
+		return nil
+	}
+	return v
+}
+
+// candidate 候选验证码及其与最近关键词的距离
+type candidate struct {
+	code     string
+	start    int
+	distance int
+}
+
+// Codes 按可信度返回文本中的候选验证码（最多 MaxCodes 个）
+func Codes(s string) []string {
+	keywords := findKeywords(s)
+	if len(keywords) == 0 {
+		return nil
+	}
+	urls := urlPattern.FindAllStringIndex(s, -1)
+
+	var candidates []candidate
+	tokens := tokenPattern.FindAllStringIndex(s, -1)
+	for i := 0; i < len(tokens); i++ {
+		start, end := tokens[i][0], tokens[i][1]
+		code := s[start:end]
+		// 123-456、123 456 这样分组的数字合并为一个验证码
+		if isDigits(code) && len(code) == 3 && i+1 < len(tokens) {
+			next := tokens[i+1]
+			if next[0] == end+1 && (s[end] == '-' || s[end] == ' ') && next[1]-next[0] == 3 && isDigits(s[next[0]:next[1]]) {
+				code += s[next[0]:next[1]]
+				end = next[1]
+				i++
+			}
+		}
+
+		numeric := isDigits(code)
+		if len(code) < 4 || len(code) > 8 || (!numeric && !isAlnumCode(code)) {
+			continue
+		}
+		if inSpans(urls, start) || joined(s, start, end) {
+			continue
+		}
+		distance, ok := nearestKeyword(keywords, start, end)
+		if !ok || (!numeric && distance > windowAlnum) || (isYear(code) && distance > 4) {
+			continue
+		}
+		if !numeric {
+			distance++ // 同等距离下数字验证码优先
+		}
+		candidates = append(candidates, candidate{code: code, start: start, distance: distance})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	var codes []string
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if seen[c.code] {
+			continue
+		}
+		seen[c.code] = true
+		codes = append(codes, c.code)
+		if len(codes) == MaxCodes {
+			break
+		}
+	}
+	return codes
+}
+
+// findKeywords 查找文本中所有验证码关键词的位置
+func findKeywords(s string) [][2]int {
+	lower := strings.ToLower(s)
+	if len(lower) != len(s) {
+		lower = asciiLower(s) // 大小写转换改变了长度时只转换 ASCII，保证位置一致
+	}
+	var spans [][2]int
+	for _, kw := range codeKeywords {
+		for offset := 0; ; {
+			i := strings.Index(lower[offset:], kw.word)
+			if i < 0 {
+				break
+			}
+			start, end := offset+i, offset+i+len(kw.word)
+			offset = end
+			if isLetterBefore(lower, start) || (kw.whole && isLetterAfter(lower, end)) || otherCode(lower[:start]) {
+				continue
+			}
+			spans = append(spans, [2]int{start, end})
+		}
+	}
+	return spans
+}
+
+// otherCode 关键词前面是否为 otherCodes 中的词
+func otherCode(before string) bool {
+	for _, prefix := range otherCodes {
+		if strings.HasSuffix(before, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nearestKeyword 候选到最近关键词的距离（关键词在候选之后时距离加倍）
+func nearestKeyword(keywords [][2]int, start, end int) (int, bool) {
+	best, found := 0, false
+	for _, kw := range keywords {
+		var d int
+		switch {
+		case kw[1] <= start && start-kw[1] <= windowBefore:
+			d = start - kw[1]
+		case kw[0] >= end && kw[0]-end <= windowAfter:
+			d = (kw[0] - end) * 2
+		default:
+			continue
+		}
+		if !found || d < best {
+			best, found = d, true
+		}
+	}
+	return best, found
+}
+
+// joined 候选是否是更长内容的一部分：金额、订单号、百分比、邮箱地址、路径、小数、时间或电话号码片段
+func joined(s string, start, end int) bool {
+	if start > 0 {
+		prev, size := utf8.DecodeLastRuneInString(s[:start])
+		if strings.ContainsRune("$€£¥#@/\\_+", prev) {
+			return true
+		}
+		if strings.ContainsRune(".:,-", prev) {
+			if before, _ := utf8.DecodeLastRuneInString(s[:start-size]); isASCIIAlnum(before) {
+				return true
+			}
+		}
+	}
+	if end < len(s) {
+		next, size := utf8.DecodeRuneInString(s[end:])
+		if strings.ContainsRune("%@/\\_", next) {
+			return true
+		}
+		if strings.ContainsRune(".:,-", next) {
+			if after, _ := utf8.DecodeRuneInString(s[end+size:]); isASCIIAlnum(after) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Links 返回正文中的验证链接（最多 MaxLinks 个），HTML 中的链接在前
+func Links(text, htmlBody string) []string {
+	var links []string
+	seen := map[string]bool{}
+	add := func(link string) {
+		if len(links) < MaxLinks && !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+
+	for _, a := range anchors(htmlBody) {
+		if isRemoteLink(a.href) && !excludedLink(a.href) && (hasLinkKeyword(a.href) || hasLinkKeyword(a.text)) {
+			add(a.href)
+		}
+	}
+	for _, link := range urlPattern.FindAllString(text, -1) {
+		link = strings.TrimRight(link, ".,;:!?)]}>'\"")
+		if !excludedLink(link) && hasLinkKeyword(link) {
+			add(link)
+		}
+	}
+	return links
+}
+
+// anchor HTML 中的链接
+type anchor struct {
+	href string
+	text string
+}
+
+// anchors 列出 HTML 中 <a> 的地址和文字
+func anchors(htmlBody string) []anchor {
+	if htmlBody == "" {
+		return nil
+	}
+	var result []anchor
+	var current *anchor
+	var text strings.Builder
+	z := html.NewTokenizer(strings.NewReader(htmlBody))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return result
+		case html.StartTagToken:
+			token := z.Token()
+			if token.DataAtom != atom.A {
+				continue
+			}
+			for _, attr := range token.Attr {
+				if strings.EqualFold(attr.Key, "href") {
+					current = &anchor{href: strings.TrimSpace(attr.Val)}
+					text.Reset()
+				}
+			}
+		case html.TextToken:
+			if current != nil {
+				text.Write(z.Text())
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); current != nil && string(name) == "a" {
+				current.text = text.String()
+				result = append(result, *current)
+				current = nil
+			}
+		}
+	}
+}
+
+func isRemoteLink(link string) bool {
+	lower := strings.ToLower(link)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+func hasLinkKeyword(s string) bool {
+	lower := strings.ToLower(s)
+	for _, kw := range linkKeywords {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+func excludedLink(link string) bool {
+	lower := strings.ToLower(link)
+	for _, kw := range linkExcludes {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAlnumCode 大写字母和数字混合（至少各一个）的验证码，如 A7K2QX
+func isAlnumCode(s string) bool {
+	digits, letters := 0, 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r >= 'A' && r <= 'Z':
+			letters++
+		default:
+			return false
+		}
+	}
+	return digits > 0 && letters > 0
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isYear 1900–2099 的四位数字
+func isYear(s string) bool {
+	return len(s) == 4 && isDigits(s) && (strings.HasPrefix(s, "19") || strings.HasPrefix(s, "20"))
+}
+
+func inSpans(spans [][]int, pos int) bool {
+	for _, span := range spans {
+		if pos >= span[0] && pos < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func isASCIIAlnum(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func isLetterBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsLetter(r) && r < utf8.RuneSelf
+}
+
+func isLetterAfter(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return unicode.IsLetter(r) && r < utf8.RuneSelf
+}
+
+// asciiLower 只把 ASCII 字母转为小写，保持字节位置不变
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
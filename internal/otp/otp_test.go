@@ -0,0 +1,71 @@
+package otp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"英文验证码", "Your verification code is 482913. It expires in 10 minutes.", []string{"482913"}},
+		{"中文验证码", "【示例】您的验证码为：735104，5分钟内有效。", []string{"735104"}},
+		{"关键词在验证码之后", "836201 is your Example login code.", []string{"836201"}},
+		{"分组数字", "Your one-time code: 123-456", []string{"123456"}},
+		{"字母数字混合", "Use code A7K2QX to sign in.", []string{"A7K2QX"}},
+		{"按距离排序", "Order 55512345 shipped. Your security code is 9921.", []string{"9921", "55512345"}},
+		{"没有关键词", "Order 482913 has shipped.", nil},
+		{"年份和金额", "Code valid until 2031. Total $1299 paid, 2025 edition.", nil},
+		{"URL 和邮箱中的数字", "Verify at https://example.com/v/123456 or mail 99887766@example.com", nil},
+		{"时间和电话", "Verification call at 12:3045 from 555-1234-5678.", nil},
+		{"邮编不是验证码", "Please enter your zip code 90210 below.", nil},
+		{"整词匹配", "Shipping 482913 soon.", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Codes(tt.text))
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	t.Run("HTML 正文", func(t *testing.T) {
+		body := `<html><body>
+<p>Hi, please confirm your email address.</p>
+<p>Your code: <b>650021</b></p>
+<a href="https://app.example.com/confirm?token=abc">Confirm email</a>
+<a href="https://app.example.com/go/xyz">点击验证</a>
+<a href="https://app.example.com/unsubscribe?token=abc">Unsubscribe</a>
+<a href="https://app.example.com/blog">Blog</a>
+</body></html>`
+		v := Extract("Confirm your account", "", body)
+		require.NotNil(t, v)
+		assert.Equal(t, "650021", v.Code)
+		assert.Equal(t, []string{
+			"https://app.example.com/confirm?token=abc",
+			"https://app.example.com/go/xyz",
+		}, v.Links)
+	})
+
+	t.Run("纯文本中的链接", func(t *testing.T) {
+		v := Extract("Sign in to Example", "Click https://example.com/magic-link/eyJhbGci. to sign in.", "")
+		require.NotNil(t, v)
+		assert.Empty(t, v.Code)
+		assert.Equal(t, []string{"https://example.com/magic-link/eyJhbGci"}, v.Links)
+	})
+
+	t.Run("主题中的验证码", func(t *testing.T) {
+		v := Extract("371920 是您的验证码", "如非本人操作请忽略。", "")
+		require.NotNil(t, v)
+		assert.Equal(t, "371920", v.Code)
+	})
+
+	t.Run("普通邮件", func(t *testing.T) {
+		assert.Nil(t, Extract("Weekly newsletter", "Read more at https://example.com/blog/2024", ""))
+	})
+}
@@ -0,0 +1,242 @@
+// Package pop3 POP3（RFC 1939）收信服务
+//
+// 用户名为邮箱地址、密码为邮箱令牌，供自动化工具和旧客户端轮询临时邮箱。登录后锁定邮箱并对邮件列表
+// 做快照（不含隔离区邮件）；RETR 经 MessageService 读取原始邮件（文件系统存储）并标记已读，TOP 不改变
+// 已读状态；DELE 的邮件在 QUIT 时才删除，连接异常断开时不删除。维护只读模式下 DELE 返回 -ERR [SYS/TEMP]，
+// QUIT 不删除邮件。
+package pop3
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ErrServerClosed 服务已关闭，Serve/ListenAndServe 在 Shutdown 或 Close 后返回
+var ErrServerClosed = errors.New("pop3: server closed")
+
+const (
+	// DefaultAddr 默认监听地址
+	DefaultAddr = ":110"
+	// DefaultIdleTimeout 默认空闲超时（RFC 1939 要求至少 10 分钟）
+	DefaultIdleTimeout = 10 * time.Minute
+	// commandTimeout 单条命令访问存储的超时
+	commandTimeout = 30 * time.Second
+	// writeTimeout 单条响应的写超时
+	writeTimeout = time.Minute
+)
+
+// MailboxLookup 按地址查询邮箱（service.MailboxService 实现）
+type MailboxLookup interface {
+	GetByAddress(ctx context.Context, address string) (*domain.Mailbox, error)
+}
+
+// MessageStore 邮件读取、标记已读和删除（service.MessageService 实现）
+type MessageStore interface {
+	List(ctx context.Context, mailboxID string) ([]domain.Message, error)
+	Get(ctx context.Context, mailboxID, messageID string) (*domain.Message, error)
+	MarkRead(ctx context.Context, mailboxID, messageID string) error
+	Delete(ctx context.Context, mailboxID, messageID string) error
+}
+
+// MaintenanceChecker 维护模式状态（service.ConfigService 实现）
+type MaintenanceChecker interface {
+	MaintenanceState() (readOnly bool, message string)
+}
+
+// Server POP3 服务
+type Server struct {
+	Addr        string        // 监听地址，为空时使用 DefaultAddr
+	Domain      string        // 问候语中的服务器名
+	IdleTimeout time.Duration // 空闲超时，为空时使用 DefaultIdleTimeout
+
+	mailboxes   MailboxLookup
+	messages    MessageStore
+	maintenance MaintenanceChecker // 可选：维护只读模式下拒绝删除邮件
+	log         *zap.Logger
+	baseCtx     context.Context
+
+	closing   atomic.Bool
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	sessions  map[*session]struct{}
+	locked    map[string]struct{} // 已被会话锁定的邮箱 ID
+	wg        sync.WaitGroup
+}
+
+// NewServer 创建 POP3 服务
+func NewServer(mailboxes MailboxLookup, messages MessageStore, log *zap.Logger) *Server {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	return &Server{
+		mailboxes: mailboxes,
+		messages:  messages,
+		log:       log,
+		baseCtx:   context.Background(),
+		listeners: make(map[net.Listener]struct{}),
+		sessions:  make(map[*session]struct{}),
+		locked:    make(map[string]struct{}),
+	}
+}
+
+// SetMaintenanceChecker 设置维护模式状态来源
+func (s *Server) SetMaintenanceChecker(checker MaintenanceChecker) {
+	s.maintenance = checker
+}
+
+// SetBaseContext 设置会话访问存储的基础上下文（随关闭信号取消）
+func (s *Server) SetBaseContext(ctx context.Context) {
+	s.baseCtx = ctx
+}
+
+// ListenAndServe 监听 Addr 并处理连接，关闭后返回 ErrServerClosed
+func (s *Server) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve 在 listener 上接受连接，每个连接一个会话，关闭后返回 ErrServerClosed
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closing.Load() {
+		s.mu.Unlock()
+		_ = listener.Close()
+		return ErrServerClosed
+	}
+	s.listeners[listener] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, listener)
+		s.mu.Unlock()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.closing.Load() {
+				return ErrServerClosed
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(50 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		sess := newSession(s, conn)
+		s.mu.Lock()
+		if s.closing.Load() {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+		s.sessions[sess] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.sessions, sess)
+				s.mu.Unlock()
+			}()
+			sess.serve()
+		}()
+	}
+}
+
+// Shutdown 停止接受新连接，空闲会话立即断开，正在执行命令的会话完成当前命令后断开（未 QUIT 的删除标记
+// 不生效）；ctx 到期时强制关闭剩余连接
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.closing.Store(true)
+	s.mu.Lock()
+	for listener := range s.listeners {
+		_ = listener.Close()
+	}
+	for sess := range s.sessions {
+		sess.interruptIfIdle()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		_ = s.Close()
+		return ctx.Err()
+	}
+}
+
+// Close 立即关闭监听和所有连接
+func (s *Server) Close() error {
+	s.closing.Store(true)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for listener := range s.listeners {
+		_ = listener.Close()
+	}
+	for sess := range s.sessions {
+		_ = sess.conn.Close()
+	}
+	return nil
+}
+
+// lock 锁定邮箱，已被其他会话锁定时返回 false
+func (s *Server) lock(mailboxID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.locked[mailboxID]; ok {
+		return false
+	}
+	s.locked[mailboxID] = struct{}{}
+	return true
+}
+
+// unlock 释放邮箱锁
+func (s *Server) unlock(mailboxID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locked, mailboxID)
+}
+
+func (s *Server) idleTimeout() time.Duration {
+	if s.IdleTimeout > 0 {
+		return s.IdleTimeout
+	}
+	return DefaultIdleTimeout
+}
+
+// maintenanceState 维护只读模式是否开启，开启时返回维护说明
+func (s *Server) maintenanceState() (bool, string) {
+	if s.maintenance == nil {
+		return false, ""
+	}
+	readOnly, message := s.maintenance.MaintenanceState()
+	if message == "" {
+		message = "service temporarily unavailable for maintenance, try again later"
+	}
+	return readOnly, message
+}
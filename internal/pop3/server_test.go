@@ -0,0 +1,318 @@
+package pop3
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/filesystem"
+	"tempmail/backend/internal/storage/memory"
+)
+
+const (
+	testAddress = "inbox@temp.example"
+	testToken   = "secret-token"
+)
+
+type testEnv struct {
+	server   *Server
+	addr     string
+	store    *memory.Store
+	messages *service.MessageService
+	ids      []string // 按投递顺序
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := memory.NewStore(time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: testAddress, LocalPart: "inbox", Domain: "temp.example",
+		Token: testToken, CreatedAt: time.Now(),
+	}))
+	fs, err := filesystem.NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.example"}}}
+	messages := service.NewMessageService(store)
+	messages.SetFilesystemStore(fs)
+
+	env := &testEnv{store: store, messages: messages}
+	for i, raw := range []string{
+		"From: a@example.com\nSubject: first\n\nhello\n.leading dot\n",
+		"From: b@example.com\r\nSubject: second\r\n\r\nline 1\r\nline 2\r\nline 3\r\n",
+	} {
+		message, err := messages.Create(t.Context(), service.CreateMessageInput{
+			MailboxID: "mb-1", From: "sender@example.com", To: testAddress, Subject: "msg",
+			Text: "body", Raw: raw, Received: time.Now().Add(time.Duration(i) * time.Second),
+		})
+		require.NoError(t, err)
+		env.ids = append(env.ids, message.ID)
+	}
+
+	env.server = NewServer(service.NewMailboxService(store, store, cfg), messages, nil)
+	env.server.Domain = "temp.example"
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	env.addr = listener.Addr().String()
+	go func() { _ = env.server.Serve(listener) }()
+	t.Cleanup(func() { _ = env.server.Close() })
+	return env
+}
+
+// maintenanceSwitch 可切换的维护模式状态
+type maintenanceSwitch struct {
+	readOnly atomic.Bool
+}
+
+func (m *maintenanceSwitch) MaintenanceState() (bool, string) {
+	return m.readOnly.Load(), "database upgrade"
+}
+
+func (env *testEnv) dial(t *testing.T) *textproto.Conn {
+	t.Helper()
+	conn, err := textproto.Dial("tcp", env.addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	expectOK(t, conn)
+	return conn
+}
+
+func (env *testEnv) login(t *testing.T) *textproto.Conn {
+	t.Helper()
+	conn := env.dial(t)
+	cmd(t, conn, "USER "+testAddress)
+	expectOK(t, conn)
+	cmd(t, conn, "PASS "+testToken)
+	expectOK(t, conn)
+	return conn
+}
+
+func cmd(t *testing.T, conn *textproto.Conn, line string) {
+	t.Helper()
+	require.NoError(t, conn.PrintfLine("%s", line))
+}
+
+func readLine(t *testing.T, conn *textproto.Conn) string {
+	t.Helper()
+	line, err := conn.ReadLine()
+	require.NoError(t, err)
+	return line
+}
+
+func expectOK(t *testing.T, conn *textproto.Conn) string {
+	t.Helper()
+	line := readLine(t, conn)
+	require.True(t, strings.HasPrefix(line, "+OK"), line)
+	return line
+}
+
+func expectErr(t *testing.T, conn *textproto.Conn) string {
+	t.Helper()
+	line := readLine(t, conn)
+	require.True(t, strings.HasPrefix(line, "-ERR"), line)
+	return line
+}
+
+// readMulti 读取多行响应（已去除字节填充）
+func readMulti(t *testing.T, conn *textproto.Conn) []string {
+	t.Helper()
+	lines, err := conn.ReadDotLines()
+	require.NoError(t, err)
+	return lines
+}
+
+func TestServer(t *testing.T) {
+	t.Run("令牌错误时拒绝登录，多次失败后断开", func(t *testing.T) {
+		env := newTestEnv(t)
+		conn := env.dial(t)
+
+		cmd(t, conn, "STAT")
+		expectErr(t, conn)
+		for range maxAuthFailures - 1 {
+			cmd(t, conn, "USER "+testAddress)
+			expectOK(t, conn)
+			cmd(t, conn, "PASS wrong")
+			assert.Contains(t, expectErr(t, conn), "[AUTH]")
+		}
+		cmd(t, conn, "USER nobody@temp.example")
+		expectOK(t, conn)
+		cmd(t, conn, "PASS "+testToken)
+		expectErr(t, conn)
+		_, err := conn.ReadLine()
+		assert.Error(t, err)
+	})
+
+	t.Run("STAT、LIST 和 UIDL 按投递顺序编号", func(t *testing.T) {
+		env := newTestEnv(t)
+		conn := env.login(t)
+
+		cmd(t, conn, "UIDL")
+		expectOK(t, conn)
+		assert.Equal(t, []string{"1 " + env.ids[0], "2 " + env.ids[1]}, readMulti(t, conn))
+
+		first := "From: a@example.com\r\nSubject: first\r\n\r\nhello\r\n.leading dot\r\n"
+		second := "From: b@example.com\r\nSubject: second\r\n\r\nline 1\r\nline 2\r\nline 3\r\n"
+		cmd(t, conn, "LIST")
+		expectOK(t, conn)
+		assert.Equal(t, []string{
+			"1 " + strconv.Itoa(len(first)), "2 " + strconv.Itoa(len(second)),
+		}, readMulti(t, conn))
+
+		cmd(t, conn, "STAT")
+		assert.Equal(t, "+OK 2 "+strconv.Itoa(len(first)+len(second)), readLine(t, conn))
+
+		cmd(t, conn, "LIST 3")
+		expectErr(t, conn)
+	})
+
+	t.Run("RETR 返回原始邮件并标记已读，TOP 不改变已读状态", func(t *testing.T) {
+		env := newTestEnv(t)
+		conn := env.login(t)
+
+		cmd(t, conn, "TOP 2 1")
+		expectOK(t, conn)
+		assert.Equal(t, []string{"From: b@example.com", "Subject: second", "", "line 1"}, readMulti(t, conn))
+		message, err := env.messages.Get(t.Context(), "mb-1", env.ids[1])
+		require.NoError(t, err)
+		assert.False(t, message.IsRead)
+
+		cmd(t, conn, "RETR 1")
+		expectOK(t, conn)
+		assert.Equal(t, []string{"From: a@example.com", "Subject: first", "", "hello", ".leading dot"}, readMulti(t, conn))
+		message, err = env.messages.Get(t.Context(), "mb-1", env.ids[0])
+		require.NoError(t, err)
+		assert.True(t, message.IsRead)
+	})
+
+	t.Run("DELE 在 QUIT 时生效，RSET 撤销", func(t *testing.T) {
+		env := newTestEnv(t)
+		conn := env.login(t)
+
+		cmd(t, conn, "DELE 1")
+		expectOK(t, conn)
+		cmd(t, conn, "RETR 1")
+		expectErr(t, conn)
+		cmd(t, conn, "RSET")
+		expectOK(t, conn)
+		cmd(t, conn, "DELE 2")
+		expectOK(t, conn)
+		cmd(t, conn, "STAT")
+		assert.True(t, strings.HasPrefix(readLine(t, conn), "+OK 1 "))
+
+		// 断开前邮件仍在
+		list, err := env.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Len(t, list, 2)
+
+		cmd(t, conn, "QUIT")
+		expectOK(t, conn)
+		list, err = env.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, env.ids[0], list[0].ID)
+	})
+
+	t.Run("维护只读模式下 DELE 被拒绝，之前标记的邮件 QUIT 时不删除", func(t *testing.T) {
+		env := newTestEnv(t)
+		maintenance := &maintenanceSwitch{}
+		env.server.SetMaintenanceChecker(maintenance)
+		conn := env.login(t)
+
+		cmd(t, conn, "DELE 1")
+		expectOK(t, conn)
+		maintenance.readOnly.Store(true)
+		cmd(t, conn, "DELE 2")
+		assert.Contains(t, expectErr(t, conn), "[SYS/TEMP] database upgrade")
+		cmd(t, conn, "RETR 2")
+		expectOK(t, conn)
+		readMulti(t, conn)
+
+		cmd(t, conn, "QUIT")
+		assert.Contains(t, expectErr(t, conn), "1 messages not removed")
+		list, err := env.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Len(t, list, 2)
+	})
+
+	t.Run("未 QUIT 断开时不删除", func(t *testing.T) {
+		env := newTestEnv(t)
+		conn := env.login(t)
+		cmd(t, conn, "DELE 1")
+		expectOK(t, conn)
+		require.NoError(t, conn.Close())
+
+		// 连接关闭后锁释放，重新登录仍能看到两封邮件
+		require.Eventually(t, func() bool {
+			c, err := textproto.Dial("tcp", env.addr)
+			if err != nil {
+				return false
+			}
+			defer c.Close()
+			_, _ = c.ReadLine()
+			_ = c.PrintfLine("USER %s", testAddress)
+			_, _ = c.ReadLine()
+			_ = c.PrintfLine("PASS %s", testToken)
+			line, _ := c.ReadLine()
+			return strings.HasPrefix(line, "+OK maildrop locked and ready, 2 messages")
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+
+	t.Run("同一邮箱同时只能有一个会话", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t)
+
+		conn := env.dial(t)
+		cmd(t, conn, "USER "+testAddress)
+		expectOK(t, conn)
+		cmd(t, conn, "PASS "+testToken)
+		assert.Contains(t, expectErr(t, conn), "[IN-USE]")
+	})
+
+	t.Run("暂停的邮箱不能登录", func(t *testing.T) {
+		env := newTestEnv(t)
+		mailbox, err := env.store.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+		mailbox.Suspended = true
+		require.NoError(t, env.store.SaveMailbox(t.Context(), mailbox))
+
+		conn := env.dial(t)
+		cmd(t, conn, "USER "+testAddress)
+		expectOK(t, conn)
+		cmd(t, conn, "PASS "+testToken)
+		assert.Contains(t, expectErr(t, conn), "[AUTH]")
+	})
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	env := newTestEnv(t)
+	conn := env.login(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, env.server.Shutdown(ctx))
+
+	// 空闲会话被断开，删除标记不生效，新连接被拒绝
+	_, err := conn.ReadLine()
+	assert.Error(t, err)
+	_, err = net.DialTimeout("tcp", env.addr, 200*time.Millisecond)
+	assert.Error(t, err)
+	assert.ErrorIs(t, env.server.Serve(newListener(t)), ErrServerClosed)
+}
+
+func newListener(t *testing.T) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	return listener
+}
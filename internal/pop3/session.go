@@ -0,0 +1,486 @@
+package pop3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/mailfmt"
+)
+
+const (
+	// maxLineLength 命令行最大长度（RFC 2449 规定 255 字节，留出余量）
+	maxLineLength = 1024
+	// maxAuthFailures 同一连接认证失败的次数上限，超过后断开
+	maxAuthFailures = 3
+)
+
+// 会话状态（RFC 1939 第 3 节）
+type state int
+
+const (
+	stateAuthorization state = iota
+	stateTransaction
+)
+
+// entry 登录时快照的一封邮件
+type entry struct {
+	message domain.Message
+	size    int64 // 输出内容的字节数，-1 表示尚未计算
+	deleted bool
+}
+
+type session struct {
+	srv    *Server
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+
+	mu   sync.Mutex // 保护 idle，与 Server.Shutdown 协调
+	idle bool
+
+	state    state
+	user     string
+	failures int
+	mailbox  *domain.Mailbox
+	entries  []*entry
+}
+
+func newSession(srv *Server, conn net.Conn) *session {
+	return &session{
+		srv:    srv,
+		conn:   conn,
+		reader: bufio.NewReaderSize(conn, maxLineLength),
+		writer: bufio.NewWriter(conn),
+	}
+}
+
+// interruptIfIdle 会话正在等待命令时让读取立即超时返回
+func (s *session) interruptIfIdle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.idle {
+		_ = s.conn.SetReadDeadline(time.Now())
+	}
+}
+
+func (s *session) serve() {
+	defer func() {
+		if s.mailbox != nil {
+			s.srv.unlock(s.mailbox.ID)
+		}
+		_ = s.conn.Close()
+	}()
+
+	domainName := s.srv.Domain
+	if domainName == "" {
+		domainName = "localhost"
+	}
+	if !s.reply("+OK %s POP3 server ready", domainName) {
+		return
+	}
+
+	for {
+		line, err := s.readCommand()
+		if err != nil {
+			if errors.Is(err, bufio.ErrBufferFull) {
+				s.reply("-ERR line too long")
+			}
+			return
+		}
+		if !s.handle(line) {
+			return
+		}
+	}
+}
+
+// readCommand 读取一行命令；服务关闭时（包括等待期间关闭）返回错误
+func (s *session) readCommand() (string, error) {
+	s.mu.Lock()
+	_ = s.conn.SetReadDeadline(time.Now().Add(s.srv.idleTimeout()))
+	s.idle = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.idle = false
+		s.mu.Unlock()
+	}()
+
+	if s.srv.closing.Load() {
+		return "", ErrServerClosed
+	}
+	line, err := s.reader.ReadSlice('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// handle 执行一条命令，返回 false 时结束会话
+func (s *session) handle(line string) bool {
+	command, arg, _ := strings.Cut(line, " ")
+	command = strings.ToUpper(command)
+
+	switch command {
+	case "CAPA":
+		return s.capa()
+	case "QUIT":
+		return s.quit()
+	}
+
+	if s.state == stateAuthorization {
+		switch command {
+		case "USER":
+			if arg == "" {
+				return s.reply("-ERR missing username")
+			}
+			s.user = arg
+			return s.reply("+OK send mailbox token as password")
+		case "PASS":
+			return s.pass(arg)
+		default:
+			return s.reply("-ERR unknown command in AUTHORIZATION state")
+		}
+	}
+
+	switch command {
+	case "STAT":
+		return s.stat()
+	case "LIST":
+		return s.list(arg)
+	case "UIDL":
+		return s.uidl(arg)
+	case "RETR":
+		return s.retr(arg)
+	case "TOP":
+		return s.top(arg)
+	case "DELE":
+		return s.dele(arg)
+	case "RSET":
+		for _, e := range s.entries {
+			e.deleted = false
+		}
+		return s.reply("+OK")
+	case "NOOP":
+		return s.reply("+OK")
+	default:
+		return s.reply("-ERR unknown command")
+	}
+}
+
+func (s *session) capa() bool {
+	lines := []string{"USER", "UIDL", "TOP", "RESP-CODES", "PIPELINING", "EXPIRE NEVER"}
+	if s.state == stateAuthorization {
+		lines = append(lines, "IMPLEMENTATION tempmail")
+	}
+	return s.replyLines("+OK capability list follows", lines)
+}
+
+func (s *session) pass(password string) bool {
+	if s.user == "" {
+		return s.reply("-ERR USER first")
+	}
+	address := s.user
+	s.user = ""
+
+	ctx, cancel := s.commandContext()
+	defer cancel()
+	mailbox, err := s.srv.mailboxes.GetByAddress(ctx, address)
+	if err != nil || subtle.ConstantTimeCompare([]byte(mailbox.Token), []byte(password)) != 1 ||
+		(mailbox.ExpiresAt != nil && !mailbox.ExpiresAt.After(time.Now())) {
+		s.failures++
+		if s.failures >= maxAuthFailures {
+			s.reply("-ERR [AUTH] too many authentication failures")
+			return false
+		}
+		return s.reply("-ERR [AUTH] invalid mailbox address or token")
+	}
+	if mailbox.Suspended {
+		return s.reply("-ERR [AUTH] mailbox suspended")
+	}
+	if !s.srv.lock(mailbox.ID) {
+		return s.reply("-ERR [IN-USE] mailbox already locked by another session")
+	}
+
+	messages, err := s.srv.messages.List(ctx, mailbox.ID)
+	if err != nil {
+		s.srv.unlock(mailbox.ID)
+		s.srv.log.Warn("pop3 list messages failed", zap.String("mailboxId", mailbox.ID), zap.Error(err))
+		return s.reply("-ERR [SYS/TEMP] unable to open mailbox")
+	}
+	// 存储按序号倒序返回，POP3 编号从最早的邮件开始
+	domain.SortMessagesBySeq(messages)
+	slices.Reverse(messages)
+
+	s.mailbox = mailbox
+	s.entries = make([]*entry, len(messages))
+	for i := range messages {
+		s.entries[i] = &entry{message: messages[i], size: -1}
+	}
+	s.state = stateTransaction
+	return s.reply("+OK maildrop locked and ready, %d messages", len(messages))
+}
+
+func (s *session) stat() bool {
+	var count, total int64
+	for _, e := range s.entries {
+		if e.deleted {
+			continue
+		}
+		size, err := s.size(e)
+		if err != nil {
+			return s.replyStoreError(err)
+		}
+		count++
+		total += size
+	}
+	return s.reply("+OK %d %d", count, total)
+}
+
+func (s *session) list(arg string) bool {
+	if arg != "" {
+		n, e, ok := s.lookup(arg)
+		if !ok {
+			return true
+		}
+		size, err := s.size(e)
+		if err != nil {
+			return s.replyStoreError(err)
+		}
+		return s.reply("+OK %d %d", n, size)
+	}
+
+	lines := make([]string, 0, len(s.entries))
+	for i, e := range s.entries {
+		if e.deleted {
+			continue
+		}
+		size, err := s.size(e)
+		if err != nil {
+			return s.replyStoreError(err)
+		}
+		lines = append(lines, fmt.Sprintf("%d %d", i+1, size))
+	}
+	return s.replyLines(fmt.Sprintf("+OK %d messages", len(lines)), lines)
+}
+
+func (s *session) uidl(arg string) bool {
+	if arg != "" {
+		n, e, ok := s.lookup(arg)
+		if !ok {
+			return true
+		}
+		return s.reply("+OK %d %s", n, e.message.ID)
+	}
+
+	lines := make([]string, 0, len(s.entries))
+	for i, e := range s.entries {
+		if !e.deleted {
+			lines = append(lines, fmt.Sprintf("%d %s", i+1, e.message.ID))
+		}
+	}
+	return s.replyLines("+OK", lines)
+}
+
+func (s *session) retr(arg string) bool {
+	_, e, ok := s.lookup(arg)
+	if !ok {
+		return true
+	}
+	content, err := s.load(e)
+	if err != nil {
+		return s.replyStoreError(err)
+	}
+	if !s.replyContent(fmt.Sprintf("+OK %d octets", len(content)), content) {
+		return false
+	}
+
+	if !e.message.IsRead {
+		ctx, cancel := s.commandContext()
+		defer cancel()
+		if err := s.srv.messages.MarkRead(ctx, s.mailbox.ID, e.message.ID); err != nil {
+			s.srv.log.Warn("pop3 mark read failed", zap.String("messageId", e.message.ID), zap.Error(err))
+		} else {
+			e.message.IsRead = true
+		}
+	}
+	return true
+}
+
+func (s *session) top(arg string) bool {
+	msgArg, linesArg, _ := strings.Cut(arg, " ")
+	lines, err := strconv.Atoi(strings.TrimSpace(linesArg))
+	if err != nil || lines < 0 {
+		return s.reply("-ERR invalid line count")
+	}
+	_, e, ok := s.lookup(msgArg)
+	if !ok {
+		return true
+	}
+	content, err := s.load(e)
+	if err != nil {
+		return s.replyStoreError(err)
+	}
+
+	// 头部、空行，加上正文的前 lines 行
+	header, body, found := bytes.Cut(content, []byte("\r\n\r\n"))
+	if !found {
+		return s.replyContent("+OK top of message follows", content)
+	}
+	out := append(header, "\r\n\r\n"...)
+	for range lines {
+		if len(body) == 0 {
+			break
+		}
+		line, rest, _ := bytes.Cut(body, []byte("\r\n"))
+		out = append(out, line...)
+		out = append(out, "\r\n"...)
+		body = rest
+	}
+	return s.replyContent("+OK top of message follows", out)
+}
+
+func (s *session) dele(arg string) bool {
+	n, e, ok := s.lookup(arg)
+	if !ok {
+		return true
+	}
+	if readOnly, message := s.srv.maintenanceState(); readOnly {
+		return s.reply("-ERR [SYS/TEMP] %s", message)
+	}
+	e.deleted = true
+	return s.reply("+OK message %d deleted", n)
+}
+
+// quit 在 TRANSACTION 状态下进入 UPDATE 状态，删除标记为删除的邮件
+func (s *session) quit() bool {
+	if s.state != stateTransaction {
+		s.reply("+OK bye")
+		return false
+	}
+
+	ctx, cancel := s.commandContext()
+	defer cancel()
+	readOnly, message := s.srv.maintenanceState()
+	remaining, failed := 0, 0
+	for _, e := range s.entries {
+		if !e.deleted {
+			remaining++
+			continue
+		}
+		if readOnly { // DELE 之后才进入维护模式
+			failed++
+			continue
+		}
+		if err := s.srv.messages.Delete(ctx, s.mailbox.ID, e.message.ID); err != nil {
+			s.srv.log.Warn("pop3 delete message failed", zap.String("messageId", e.message.ID), zap.Error(err))
+			failed++
+		}
+	}
+	if failed > 0 && readOnly {
+		s.reply("-ERR [SYS/TEMP] %s, %d messages not removed", message, failed)
+		return false
+	}
+	if failed > 0 {
+		s.reply("-ERR [SYS/TEMP] %d messages not removed", failed)
+		return false
+	}
+	s.reply("+OK bye, %d messages left", remaining)
+	return false
+}
+
+// lookup 解析邮件编号，无效或已删除时直接回复错误并返回 false
+func (s *session) lookup(arg string) (int, *entry, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || n < 1 || n > len(s.entries) {
+		s.reply("-ERR no such message")
+		return 0, nil, false
+	}
+	e := s.entries[n-1]
+	if e.deleted {
+		s.reply("-ERR message %d already deleted", n)
+		return 0, nil, false
+	}
+	return n, e, true
+}
+
+// size 输出内容的精确字节数（首次需要时加载邮件计算，之后缓存）
+func (s *session) size(e *entry) (int64, error) {
+	if e.size >= 0 {
+		return e.size, nil
+	}
+	content, err := s.load(e)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(content)), nil
+}
+
+// load 经 MessageService 读取邮件并转换为 CRLF 换行的 RFC 5322 内容
+func (s *session) load(e *entry) ([]byte, error) {
+	ctx, cancel := s.commandContext()
+	defer cancel()
+	message, err := s.srv.messages.Get(ctx, s.mailbox.ID, e.message.ID)
+	if err != nil {
+		return nil, err
+	}
+	content := mailfmt.Render(message, s.srv.Domain)
+	e.size = int64(len(content))
+	return content, nil
+}
+
+func (s *session) commandContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(s.srv.baseCtx, commandTimeout)
+}
+
+func (s *session) replyStoreError(err error) bool {
+	s.srv.log.Warn("pop3 load message failed", zap.String("mailboxId", s.mailbox.ID), zap.Error(err))
+	return s.reply("-ERR [SYS/TEMP] unable to read message")
+}
+
+// reply 输出单行响应，写入失败时返回 false
+func (s *session) reply(format string, args ...any) bool {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	fmt.Fprintf(s.writer, format+"\r\n", args...)
+	return s.writer.Flush() == nil
+}
+
+// replyLines 输出多行响应
+func (s *session) replyLines(status string, lines []string) bool {
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteString("\r\n")
+	}
+	return s.replyContent(status, buf.Bytes())
+}
+
+// replyContent 输出多行响应：content 须以 CRLF 换行，以 "." 开头的行做字节填充，以 ".\r\n" 结束
+func (s *session) replyContent(status string, content []byte) bool {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	s.writer.WriteString(status)
+	s.writer.WriteString("\r\n")
+	for len(content) > 0 {
+		line, rest, _ := bytes.Cut(content, []byte("\r\n"))
+		if len(line) > 0 && line[0] == '.' {
+			s.writer.WriteByte('.')
+		}
+		s.writer.Write(line)
+		s.writer.WriteString("\r\n")
+		content = rest
+	}
+	s.writer.WriteString(".\r\n")
+	return s.writer.Flush() == nil
+}
@@ -0,0 +1,247 @@
+package redact
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// dropTags 脱敏副本中整体移除的标签（脚本、外部资源、表单等）
+var dropTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Link:     true,
+	atom.Meta:     true,
+	atom.Base:     true,
+	atom.Form:     true,
+	atom.Noscript: true,
+	atom.Template: true,
+}
+
+// inlineTags 行内标签：其中的文本与相邻文本连成一段再匹配，
+// 避免 <b>12</b>34 这类被标签拆开的验证码漏网
+var inlineTags = map[atom.Atom]bool{
+	atom.A: true, atom.B: true, atom.I: true, atom.U: true, atom.Em: true, atom.Strong: true,
+	atom.Span: true, atom.Font: true, atom.Code: true, atom.Small: true, atom.Sub: true, atom.Sup: true,
+	atom.Mark: true, atom.Abbr: true, atom.Bdi: true, atom.Bdo: true, atom.Kbd: true, atom.Samp: true,
+	atom.Var: true, atom.S: true, atom.Strike: true, atom.Ins: true, atom.Del: true,
+}
+
+// textAttrs 需要脱敏的属性
+var textAttrs = map[string]bool{
+	"href": true, "src": true, "title": true, "alt": true, "value": true, "content": true,
+}
+
+// urlAttrs 需要过滤危险协议的属性
+var urlAttrs = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true, "background": true, "poster": true, "cite": true,
+}
+
+// HTML 清理并遮盖 HTML 正文
+//
+// 移除脚本、事件属性和危险协议链接后，对文本节点和链接等属性应用脱敏规则；
+// 实体编码（&#49;&#50;）在解析时已解码，同样会被命中。返回 body 内的 HTML 片段。
+func (r *Redactor) HTML(s string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+	body := findBody(doc)
+	if body == nil {
+		return "", nil
+	}
+
+	sanitize(body)
+	r.redactNode(body)
+
+	var buf bytes.Buffer
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+// SanitizeHTML 只清理 HTML 正文（移除脚本、事件属性和危险协议链接），不做脱敏
+//
+// 用于无需认证即可查看的场景（如公开收件箱），返回 body 内的 HTML 片段。
+func SanitizeHTML(s string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+	body := findBody(doc)
+	if body == nil {
+		return "", nil
+	}
+
+	sanitize(body)
+
+	var buf bytes.Buffer
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+func findBody(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Body {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if body := findBody(c); body != nil {
+			return body
+		}
+	}
+	return nil
+}
+
+// sanitize 移除危险元素和属性
+func sanitize(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.CommentNode:
+			n.RemoveChild(c)
+		case html.ElementNode:
+			if dropTags[c.DataAtom] {
+				n.RemoveChild(c)
+			} else {
+				c.Attr = sanitizeAttrs(c.Attr)
+				sanitize(c)
+			}
+		}
+		c = next
+	}
+}
+
+func sanitizeAttrs(attrs []html.Attribute) []html.Attribute {
+	kept := attrs[:0]
+	for _, a := range attrs {
+		key := strings.ToLower(a.Key)
+		if strings.HasPrefix(key, "on") || key == "srcdoc" {
+			continue
+		}
+		if urlAttrs[key] && unsafeURL(a.Val) {
+			continue
+		}
+		kept = append(kept, a)
+	}
+	return kept
+}
+
+// unsafeURL 判断是否为 javascript:、vbscript: 或 data: 链接（忽略空白和控制字符混淆）
+func unsafeURL(value string) bool {
+	var b strings.Builder
+	for _, r := range value {
+		if r > ' ' {
+			b.WriteRune(r)
+		}
+	}
+	v := strings.ToLower(b.String())
+	return strings.HasPrefix(v, "javascript:") || strings.HasPrefix(v, "vbscript:") || strings.HasPrefix(v, "data:")
+}
+
+// redactNode 遮盖节点下的文本段和属性
+func (r *Redactor) redactNode(n *html.Node) {
+	var run []*html.Node
+	flush := func() {
+		r.redactRun(run)
+		run = run[:0]
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode:
+			run = append(run, c)
+		case c.Type == html.ElementNode && inlineTags[c.DataAtom]:
+			r.redactAttrs(c)
+			run = append(run, inlineText(r, c)...)
+		case c.Type == html.ElementNode:
+			flush()
+			r.redactAttrs(c)
+			r.redactNode(c)
+		}
+	}
+	flush()
+}
+
+// inlineText 行内元素中的文本节点（按文档顺序），嵌套的块级元素单独处理
+func inlineText(r *Redactor, n *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode:
+			nodes = append(nodes, c)
+		case c.Type == html.ElementNode && inlineTags[c.DataAtom]:
+			r.redactAttrs(c)
+			nodes = append(nodes, inlineText(r, c)...)
+		case c.Type == html.ElementNode:
+			r.redactAttrs(c)
+			r.redactNode(c)
+		}
+	}
+	return nodes
+}
+
+// redactRun 将相邻文本节点拼接后匹配，命中区间起点所在节点写入遮罩，其余部分清空
+func (r *Redactor) redactRun(nodes []*html.Node) {
+	if len(nodes) == 0 {
+		return
+	}
+	if len(nodes) == 1 {
+		nodes[0].Data = r.Text(nodes[0].Data)
+		return
+	}
+
+	var joined strings.Builder
+	offsets := make([]int, len(nodes)+1)
+	for i, n := range nodes {
+		offsets[i] = joined.Len()
+		joined.WriteString(n.Data)
+	}
+	offsets[len(nodes)] = joined.Len()
+
+	spans := r.spans(joined.String())
+	if len(spans) == 0 {
+		return
+	}
+
+	for i, n := range nodes {
+		start, end := offsets[i], offsets[i+1]
+		var b strings.Builder
+		pos := start
+		for _, sp := range spans {
+			if sp.end <= start || sp.start >= end {
+				continue
+			}
+			if sp.start > pos {
+				b.WriteString(joined.String()[pos:sp.start])
+			}
+			if sp.start >= start {
+				b.WriteString(Mask)
+			}
+			pos = min(sp.end, end)
+		}
+		if pos < end {
+			b.WriteString(joined.String()[pos:end])
+		}
+		n.Data = b.String()
+	}
+}
+
+func (r *Redactor) redactAttrs(n *html.Node) {
+	for i, a := range n.Attr {
+		if textAttrs[strings.ToLower(a.Key)] {
+			n.Attr[i].Val = r.Text(a.Val)
+		}
+	}
+}
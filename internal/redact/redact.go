@@ -0,0 +1,299 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Version 内置规则集版本
+//
+// 内置规则的匹配行为变化时递增；脱敏副本记录生成时的版本，规则更新后可据此重新生成。
+const Version = 1
+
+// Mask 替换命中内容的遮罩（固定长度，不泄露原文长度）
+const Mask = "██"
+
+// 内置规则
+const (
+	RuleEmails    = "emails"     // 邮箱地址
+	RulePhones    = "phones"     // 电话号码（7–15 位数字）
+	RuleTokenURLs = "token_urls" // URL 中的令牌参数和令牌路径段
+	RuleCodes     = "codes"      // 4–8 位数字验证码（含全角等 Unicode 数字）
+)
+
+// 自定义正则限制
+const (
+	MaxPatterns      = 10
+	MaxPatternLength = 256
+)
+
+var (
+	ErrNoRules         = errors.New("no redaction rules specified")
+	ErrUnknownRule     = errors.New("unknown redaction rule")
+	ErrInvalidPattern  = errors.New("invalid redaction pattern")
+	ErrTooManyPatterns = errors.New("too many redaction patterns")
+)
+
+// span 命中区间（字节偏移，左闭右开）
+type span struct {
+	start, end int
+}
+
+// matcher 在文本中查找需要遮盖的区间
+type matcher func(s string) []span
+
+var builtins = map[string]matcher{
+	RuleEmails:    matchEmails,
+	RulePhones:    matchPhones,
+	RuleTokenURLs: matchTokenURLs,
+	RuleCodes:     matchCodes,
+}
+
+// BuiltinRules 全部内置规则名（按名称排序）
+func BuiltinRules() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Redactor 按一组规则遮盖文本和 HTML 中的敏感内容
+//
+// 相同的规则和输入总是得到相同的输出。
+type Redactor struct {
+	rules    []string
+	patterns []string
+	matchers []matcher
+}
+
+// New 创建脱敏器：rules 为内置规则名，patterns 为自定义正则（RE2 语法）
+func New(rules, patterns []string) (*Redactor, error) {
+	if len(rules) == 0 && len(patterns) == 0 {
+		return nil, ErrNoRules
+	}
+	if len(patterns) > MaxPatterns {
+		return nil, ErrTooManyPatterns
+	}
+
+	r := &Redactor{}
+	seen := make(map[string]bool, len(rules))
+	for _, name := range rules {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if seen[name] {
+			continue
+		}
+		if _, ok := builtins[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRule, name)
+		}
+		seen[name] = true
+		r.rules = append(r.rules, name)
+	}
+	sort.Strings(r.rules)
+	for _, name := range r.rules {
+		r.matchers = append(r.matchers, builtins[name])
+	}
+
+	for _, pattern := range patterns {
+		if pattern == "" || len(pattern) > MaxPatternLength {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPattern, pattern)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPattern, err)
+		}
+		r.patterns = append(r.patterns, pattern)
+		r.matchers = append(r.matchers, regexpMatcher(re))
+	}
+	return r, nil
+}
+
+// Rules 生效的内置规则（已去重排序）
+func (r *Redactor) Rules() []string {
+	return append([]string(nil), r.rules...)
+}
+
+// Patterns 自定义正则（保持提交顺序）
+func (r *Redactor) Patterns() []string {
+	return append([]string(nil), r.patterns...)
+}
+
+// Text 遮盖纯文本中的命中内容
+func (r *Redactor) Text(s string) string {
+	return apply(s, r.spans(s))
+}
+
+// spans 所有规则的命中区间（排序并合并重叠、相邻区间）
+func (r *Redactor) spans(s string) []span {
+	var all []span
+	for _, m := range r.matchers {
+		all = append(all, m(s)...)
+	}
+	if len(all) == 0 {
+		return nil
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].start != all[j].start {
+			return all[i].start < all[j].start
+		}
+		return all[i].end > all[j].end
+	})
+	merged := []span{all[0]}
+	for _, sp := range all[1:] {
+		last := &merged[len(merged)-1]
+		if sp.start <= last.end {
+			last.end = max(last.end, sp.end)
+			continue
+		}
+		merged = append(merged, sp)
+	}
+	return merged
+}
+
+// apply 将区间替换为遮罩
+func apply(s string, spans []span) string {
+	if len(spans) == 0 {
+		return s
+	}
+	var b strings.Builder
+	prev := 0
+	for _, sp := range spans {
+		b.WriteString(s[prev:sp.start])
+		b.WriteString(Mask)
+		prev = sp.end
+	}
+	b.WriteString(s[prev:])
+	return b.String()
+}
+
+func regexpMatcher(re *regexp.Regexp) matcher {
+	return func(s string) []span {
+		var result []span
+		for _, loc := range re.FindAllStringIndex(s, -1) {
+			if loc[1] > loc[0] {
+				result = append(result, span{loc[0], loc[1]})
+			}
+		}
+		return result
+	}
+}
+
+var emailPattern = regexp.MustCompile(`[\p{L}\p{N}._%+\-]+@[\p{L}\p{N}\-]+(?:\.[\p{L}\p{N}\-]+)*\.\p{L}{2,}`)
+
+func matchEmails(s string) []span {
+	return regexpMatcher(emailPattern)(s)
+}
+
+// phonePattern 候选电话号码：可选 +，数字之间只允许单个空格、点、横线或括号
+var phonePattern = regexp.MustCompile(`\+?\(?\p{Nd}(?:[ .\-]?\(?\p{Nd}\)?)+`)
+
+func matchPhones(s string) []span {
+	var result []span
+	for _, loc := range phonePattern.FindAllStringIndex(s, -1) {
+		candidate := s[loc[0]:loc[1]]
+		digits := 0
+		for _, r := range candidate {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		if digits < 7 || digits > 15 || !bounded(s, loc[0], loc[1]) {
+			continue
+		}
+		result = append(result, span{loc[0], loc[1]})
+	}
+	return result
+}
+
+var digitRun = regexp.MustCompile(`\p{Nd}+`)
+
+// matchCodes 4–8 位的独立数字串（前后不是字母或数字，URL 路径和参数中的验证码同样命中）
+func matchCodes(s string) []span {
+	var result []span
+	for _, loc := range digitRun.FindAllStringIndex(s, -1) {
+		n := utf8.RuneCountInString(s[loc[0]:loc[1]])
+		if n < 4 || n > 8 || !bounded(s, loc[0], loc[1]) {
+			continue
+		}
+		result = append(result, span{loc[0], loc[1]})
+	}
+	return result
+}
+
+// bounded 判断区间前后是否都不是字母或数字
+func bounded(s string, start, end int) bool {
+	if start > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(s[:start]); unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	if end < len(s) {
+		if r, _ := utf8.DecodeRuneInString(s[end:]); unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	urlPattern   = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'` + "`" + `]+`)
+	paramPattern = regexp.MustCompile(`[?&#;]([A-Za-z0-9_.\-\[\]]+)=([^&#;]*)`)
+	tokenSegment = regexp.MustCompile(`/([A-Za-z0-9_\-.~%]{20,})`)
+)
+
+// sensitiveParams 值需要遮盖的 URL 参数名（小写）
+var sensitiveParams = map[string]bool{
+	"token": true, "access_token": true, "refresh_token": true, "id_token": true, "auth": true,
+	"code": true, "otp": true, "pin": true, "key": true, "api_key": true, "apikey": true,
+	"secret": true, "sig": true, "signature": true, "session": true, "sid": true,
+	"password": true, "pass": true, "jwt": true, "ticket": true, "nonce": true, "hash": true,
+	"verify": true, "verification": true, "confirm": true, "reset": true, "magic": true, "t": true,
+}
+
+// matchTokenURLs URL 中的令牌：敏感参数的值、形似令牌的参数值和路径段（保留 URL 结构）
+func matchTokenURLs(s string) []span {
+	var result []span
+	for _, loc := range urlPattern.FindAllStringIndex(s, -1) {
+		url := s[loc[0]:loc[1]]
+		for _, m := range paramPattern.FindAllStringSubmatchIndex(url, -1) {
+			name, value := strings.ToLower(url[m[2]:m[3]]), url[m[4]:m[5]]
+			if value != "" && (sensitiveParams[name] || looksLikeToken(value)) {
+				result = append(result, span{loc[0] + m[4], loc[0] + m[5]})
+			}
+		}
+		path := url
+		if i := strings.IndexAny(path, "?#"); i >= 0 {
+			path = path[:i]
+		}
+		for _, m := range tokenSegment.FindAllStringSubmatchIndex(path, -1) {
+			if looksLikeToken(path[m[2]:m[3]]) {
+				result = append(result, span{loc[0] + m[2], loc[0] + m[3]})
+			}
+		}
+	}
+	return result
+}
+
+// looksLikeToken 至少 16 个字符且同时包含字母和数字
+func looksLikeToken(value string) bool {
+	if len(value) < 16 {
+		return false
+	}
+	var letters, digits bool
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = true
+		case unicode.IsLetter(r):
+			letters = true
+		}
+	}
+	return letters && digits
+}
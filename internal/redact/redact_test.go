@@ -0,0 +1,174 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(nil, nil)
+	assert.ErrorIs(t, err, ErrNoRules)
+
+	_, err = New([]string{"emails", "ssn"}, nil)
+	assert.ErrorIs(t, err, ErrUnknownRule)
+
+	_, err = New(nil, []string{"(unclosed"})
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+
+	_, err = New(nil, []string{strings.Repeat("a", MaxPatternLength+1)})
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+
+	_, err = New(nil, make([]string, MaxPatterns+1))
+	assert.ErrorIs(t, err, ErrTooManyPatterns)
+
+	r, err := New([]string{"Codes", "emails", "codes"}, []string{`ACME-\d+`})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"codes", "emails"}, r.Rules())
+	assert.Equal(t, []string{`ACME-\d+`}, r.Patterns())
+}
+
+func TestText(t *testing.T) {
+	all, err := New(BuiltinRules(), nil)
+	require.NoError(t, err)
+
+	t.Run("邮箱地址", func(t *testing.T) {
+		r, _ := New([]string{RuleEmails}, nil)
+		assert.Equal(t, "联系 ██ 或 ██。", r.Text("联系 alice.w+qa@example.co.uk 或 张三@例子.中国。"))
+	})
+
+	t.Run("电话号码", func(t *testing.T) {
+		r, _ := New([]string{RulePhones}, nil)
+		assert.Equal(t, "call ██ now", r.Text("call +1 (415) 555-0132 now"))
+		assert.Equal(t, "手机 ██", r.Text("手机 138-0013-8000"))
+		assert.Equal(t, "order 12345 ok", r.Text("order 12345 ok"), "位数不足不是电话")
+		assert.Equal(t, "id A1234567890", r.Text("id A1234567890"), "嵌在字母中的数字串不是电话")
+	})
+
+	t.Run("验证码", func(t *testing.T) {
+		r, _ := New([]string{RuleCodes}, nil)
+		assert.Equal(t, "Your code is ██.", r.Text("Your code is 482913."))
+		assert.Equal(t, "验证码：██", r.Text("验证码：４８２９１３"), "全角数字")
+		assert.Equal(t, "code ██ ok", r.Text("code ٤٨٢٩ ok"), "阿拉伯-印度数字")
+		assert.Equal(t, "123 and 123456789", r.Text("123 and 123456789"), "长度范围外不遮盖")
+		assert.Equal(t, "v2.0 ABC1234", r.Text("v2.0 ABC1234"))
+	})
+
+	t.Run("URL 中的令牌", func(t *testing.T) {
+		r, _ := New([]string{RuleTokenURLs}, nil)
+		assert.Equal(t,
+			"https://app.example.com/verify?token=██&lang=en",
+			r.Text("https://app.example.com/verify?token=abc.DEF-123&lang=en"))
+		assert.Equal(t,
+			"https://example.com/reset/██/confirm#access_token=██",
+			r.Text("https://example.com/reset/eyJhbGciOiJIUzI1NiJ9abc123/confirm#access_token=xyz"))
+		assert.Equal(t,
+			"https://example.com/docs/getting-started-guide-for-users",
+			r.Text("https://example.com/docs/getting-started-guide-for-users"), "普通路径不遮盖")
+	})
+
+	t.Run("URL 路径中的验证码", func(t *testing.T) {
+		assert.Equal(t, "https://example.com/verify/██", all.Text("https://example.com/verify/482913"))
+	})
+
+	t.Run("自定义正则", func(t *testing.T) {
+		r, err := New(nil, []string{`ACME-\d+`, `(?i)internal project \w+`})
+		require.NoError(t, err)
+		assert.Equal(t, "ticket ██ for ██", r.Text("ticket ACME-42 for Internal Project Falcon"))
+	})
+
+	t.Run("重叠命中合并为一个遮罩", func(t *testing.T) {
+		assert.Equal(t, "mail ██ please", all.Text("mail 123456@example.com please"))
+	})
+
+	t.Run("结果确定", func(t *testing.T) {
+		input := "a@b.io 482913 +86 138 0013 8000 https://x.io/?code=99"
+		assert.Equal(t, all.Text(input), all.Text(input))
+	})
+}
+
+func TestHTML(t *testing.T) {
+	r, err := New([]string{RuleCodes, RuleEmails, RuleTokenURLs}, nil)
+	require.NoError(t, err)
+
+	t.Run("被标签拆开的验证码", func(t *testing.T) {
+		out, err := r.HTML(`<p>Code: <b>48</b><span>29</span>13</p>`)
+		require.NoError(t, err)
+		assert.Equal(t, `<p>Code: <b>██</b><span></span></p>`, out)
+	})
+
+	t.Run("实体编码的验证码", func(t *testing.T) {
+		out, err := r.HTML(`<p>Code: &#52;&#56;&#50;&#57;&#49;&#51;</p>`)
+		require.NoError(t, err)
+		assert.Equal(t, `<p>Code: ██</p>`, out)
+	})
+
+	t.Run("块级元素之间不拼接", func(t *testing.T) {
+		out, err := r.HTML(`<div>12</div><div>34</div>`)
+		require.NoError(t, err)
+		assert.Equal(t, `<div>12</div><div>34</div>`, out)
+	})
+
+	t.Run("链接属性", func(t *testing.T) {
+		out, err := r.HTML(`<a href="https://example.com/login?token=s3cr3t" title="mail bob@example.com">登录</a>`)
+		require.NoError(t, err)
+		assert.Equal(t, `<a href="https://example.com/login?token=██" title="mail ██">登录</a>`, out)
+	})
+
+	t.Run("清理脚本和事件属性", func(t *testing.T) {
+		out, err := r.HTML(`<html><head><style>p{}</style></head><body>` +
+			`<p onclick="steal()">hi</p><script>alert(1)</script>` +
+			`<a href=" javascript:alert(1)">x</a><img src="data:image/png;base64,AAAA"><!-- 482913 --></body></html>`)
+		require.NoError(t, err)
+		assert.Equal(t, `<p>hi</p><a>x</a><img/>`, out)
+	})
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	out, err := SanitizeHTML(`<p onclick="steal()">Code 482913</p><script>alert(1)</script>` +
+		`<a href="vbscript:x">a</a><a href="https://example.com/?token=abc">b</a>`)
+	require.NoError(t, err)
+	assert.Equal(t, `<p>Code 482913</p><a>a</a><a href="https://example.com/?token=abc">b</a>`, out)
+}
+
+func TestSafeHTML(t *testing.T) {
+	const body = `<p>Hi<script>alert(1)</script></p><form action="https://evil.example/post"><input name="pw"></form>` +
+		`<img src="https://cdn.example/logo.png" srcset="https://cdn.example/logo@2x.png 2x" alt="logo">` +
+		`<img src="https://track.example/open.gif" width="1" height="1">` +
+		`<img src="https://track.example/hidden.gif" style="display: none">` +
+		`<img src="cid:part1">` +
+		`<a href="https://example.com" ping="https://track.example/click" rel="opener">link</a>` +
+		`<div style="background:url(https://cdn.example/bg.png)">bg</div>`
+
+	t.Run("默认拦截远程图片", func(t *testing.T) {
+		result, err := SafeHTML(body, SafeHTMLOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, `<p>Hi</p><img alt="logo"/><img src="cid:part1"/>`+
+			`<a href="https://example.com" rel="noopener noreferrer">link</a><div>bg</div>`, result.HTML)
+		assert.Equal(t, 1, result.RemoteImages)
+		assert.Equal(t, 2, result.TrackersRemoved)
+	})
+
+	t.Run("代理远程图片", func(t *testing.T) {
+		result, err := SafeHTML(body, SafeHTMLOptions{
+			RemoteImages: RemoteImagesProxy,
+			ProxyURL:     func(remote string) string { return "/proxy?u=" + remote },
+		})
+		require.NoError(t, err)
+		assert.Contains(t, result.HTML, `<img src="/proxy?u=https://cdn.example/logo.png" alt="logo"/>`)
+		assert.NotContains(t, result.HTML, "track.example")
+		assert.Equal(t, 1, result.RemoteImages)
+	})
+
+	t.Run("允许远程图片时仍移除跟踪像素", func(t *testing.T) {
+		result, err := SafeHTML(body, SafeHTMLOptions{RemoteImages: RemoteImagesAllow})
+		require.NoError(t, err)
+		assert.Contains(t, result.HTML, `srcset="https://cdn.example/logo@2x.png 2x"`)
+		assert.Contains(t, result.HTML, `style="background:url(https://cdn.example/bg.png)"`)
+		assert.NotContains(t, result.HTML, "track.example")
+		assert.Equal(t, 0, result.RemoteImages)
+		assert.Equal(t, 2, result.TrackersRemoved)
+	})
+}
@@ -0,0 +1,165 @@
+package redact
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 远程图片的处理方式
+const (
+	RemoteImagesBlock = "block" // 移除远程图片地址（默认）
+	RemoteImagesProxy = "proxy" // 改写为代理地址
+	RemoteImagesAllow = "allow" // 保留原地址
+)
+
+// SafeHTMLOptions 安全渲染选项
+type SafeHTMLOptions struct {
+	RemoteImages string              // block（默认）、proxy 或 allow
+	ProxyURL     func(string) string // proxy 模式下把远程图片地址改写为代理地址
+}
+
+// SafeHTMLResult 安全渲染结果
+type SafeHTMLResult struct {
+	HTML            string `json:"html"`
+	RemoteImages    int    `json:"remoteImages"`    // 按选项拦截或代理的远程图片数
+	TrackersRemoved int    `json:"trackersRemoved"` // 移除的跟踪像素数
+}
+
+// imageAttrs 加载图片的属性（srcset 无法逐项改写，非 allow 模式下直接移除）
+var imageAttrs = map[string]bool{"src": true, "background": true, "poster": true}
+
+// SafeHTML 生成可以直接展示的 HTML 正文
+//
+// 在 SanitizeHTML 的基础上移除跟踪像素（宽或高不超过 1 像素、或隐藏的远程图片）和 ping 属性，
+// 链接加上 rel="noopener noreferrer"，按选项拦截或代理远程图片；非 allow 模式下移除引用外部地址的行内样式。
+// 引用附件的 cid: 图片不受影响。返回 body 内的 HTML 片段。
+func SafeHTML(s string, opts SafeHTMLOptions) (*SafeHTMLResult, error) {
+	result := &SafeHTMLResult{}
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return nil, err
+	}
+	body := findBody(doc)
+	if body == nil {
+		return result, nil
+	}
+
+	sanitize(body)
+	safeNode(body, opts, result)
+
+	var buf bytes.Buffer
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return nil, err
+		}
+	}
+	result.HTML = buf.String()
+	return result, nil
+}
+
+// safeNode 处理节点下的跟踪像素、链接和远程图片
+func safeNode(n *html.Node, opts SafeHTMLOptions, result *SafeHTMLResult) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode {
+			if c.DataAtom == atom.Img && isTrackingPixel(c) {
+				n.RemoveChild(c)
+				result.TrackersRemoved++
+			} else {
+				safeAttrs(c, opts, result)
+				safeNode(c, opts, result)
+			}
+		}
+		c = next
+	}
+}
+
+func safeAttrs(n *html.Node, opts SafeHTMLOptions, result *SafeHTMLResult) {
+	mode := opts.RemoteImages
+	if mode == RemoteImagesProxy && opts.ProxyURL == nil {
+		mode = RemoteImagesBlock
+	}
+
+	kept := n.Attr[:0]
+	remote := false
+	for _, a := range n.Attr {
+		key := strings.ToLower(a.Key)
+		switch {
+		case key == "ping":
+			continue
+		case key == "rel" && n.DataAtom == atom.A:
+			continue // 统一改写
+		case mode != RemoteImagesAllow && key == "srcset":
+			continue
+		case mode != RemoteImagesAllow && key == "style" && strings.Contains(strings.ToLower(a.Val), "url("):
+			continue
+		case imageAttrs[key] && isRemoteURL(a.Val):
+			remote = true
+			switch mode {
+			case RemoteImagesAllow:
+			case RemoteImagesProxy:
+				a.Val = opts.ProxyURL(a.Val)
+			default:
+				continue
+			}
+		}
+		kept = append(kept, a)
+	}
+	n.Attr = kept
+	if remote && mode != RemoteImagesAllow {
+		result.RemoteImages++
+	}
+	if n.DataAtom == atom.A {
+		n.Attr = append(n.Attr, html.Attribute{Key: "rel", Val: "noopener noreferrer"})
+	}
+}
+
+// isTrackingPixel 远程图片的宽或高不超过 1 像素，或被样式隐藏
+func isTrackingPixel(n *html.Node) bool {
+	var src, width, height string
+	style := map[string]string{}
+	for _, a := range n.Attr {
+		switch strings.ToLower(a.Key) {
+		case "src":
+			src = a.Val
+		case "width":
+			width = a.Val
+		case "height":
+			height = a.Val
+		case "style":
+			style = parseStyle(a.Val)
+		}
+	}
+	if !isRemoteURL(src) {
+		return false
+	}
+	tiny := func(v string) bool {
+		v = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(v)), "px")
+		return v == "0" || v == "1"
+	}
+	return tiny(width) || tiny(height) || tiny(style["width"]) || tiny(style["height"]) ||
+		style["display"] == "none" || style["visibility"] == "hidden"
+}
+
+// parseStyle 解析行内样式为小写的属性 -> 值
+func parseStyle(style string) map[string]string {
+	props := map[string]string{}
+	for _, decl := range strings.Split(style, ";") {
+		name, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+		props[strings.ToLower(strings.TrimSpace(name))] = strings.ToLower(value)
+	}
+	return props
+}
+
+// isRemoteURL 是否为 http(s) 或协议相对的远程地址
+func isRemoteURL(value string) bool {
+	v := strings.ToLower(strings.TrimSpace(value))
+	return strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") || strings.HasPrefix(v, "//")
+}
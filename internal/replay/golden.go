@@ -0,0 +1,214 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// diffContext 差异两侧保留的上下文行数
+const diffContext = 2
+
+// Mismatch 与基准结果不一致的一封邮件
+type Mismatch struct {
+	File string // 邮件名；基准结果多余时为基准文件名
+	Diff string
+}
+
+// Marshal 输出键按字母排序、缩进两格的 JSON，结尾带换行
+func Marshal(record Record) ([]byte, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	// 经 map 重新编码，键按字母排序，不依赖结构体字段顺序
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(generic); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GoldenName 邮件对应的基准文件名（子目录以 "__" 连接）
+func GoldenName(file string) string {
+	name := strings.TrimSuffix(file, filepath.Ext(file))
+	return strings.ReplaceAll(name, "/", "__") + ".json"
+}
+
+// CompareGolden 逐封比较回放结果与基准目录，返回全部不一致项
+//
+// 缺少基准、内容不同以及没有对应邮件的多余基准都算不一致。
+func CompareGolden(dir string, records []Record) ([]Mismatch, error) {
+	var mismatches []Mismatch
+	expected := make(map[string]bool, len(records))
+	for _, record := range records {
+		name := GoldenName(record.File)
+		expected[name] = true
+
+		got, err := Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		want, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			mismatches = append(mismatches, Mismatch{File: record.File, Diff: "缺少基准结果 " + name + "\n" + Diff("", string(got))})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(want, got) {
+			mismatches = append(mismatches, Mismatch{File: record.File, Diff: Diff(string(want), string(got))})
+		}
+	}
+
+	stale, err := staleGoldens(dir, expected)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range stale {
+		mismatches = append(mismatches, Mismatch{File: name, Diff: "基准结果没有对应的邮件\n"})
+	}
+	return mismatches, nil
+}
+
+// WriteGolden 用回放结果覆盖基准目录，并删除没有对应邮件的基准
+func WriteGolden(dir string, records []Record) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	expected := make(map[string]bool, len(records))
+	for _, record := range records {
+		name := GoldenName(record.File)
+		expected[name] = true
+		data, err := Marshal(record)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	stale, err := staleGoldens(dir, expected)
+	if err != nil {
+		return err
+	}
+	for _, name := range stale {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// staleGoldens 基准目录中不在 expected 内的 .json 文件，按名称排序
+func staleGoldens(dir string, expected map[string]bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stale []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" && !expected[entry.Name()] {
+			stale = append(stale, entry.Name())
+		}
+	}
+	sort.Strings(stale)
+	return stale, nil
+}
+
+// Diff 按行比较 want 与 got，输出带上下文的差异（"-" 为基准，"+" 为回放结果）
+//
+// 内容相同时返回空字符串；相隔较远的改动分成多段，段间以 "@@" 分隔。
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a, b := splitLines(want), splitLines(got)
+
+	// 最长公共子序列
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			lines = append(lines, line{'+', b[j]})
+			j++
+		default:
+			lines = append(lines, line{'-', a[i]})
+			i++
+		}
+	}
+
+	// 只保留改动行及其前后 diffContext 行
+	keep := make([]bool, len(lines))
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for c := max(0, k-diffContext); c <= min(len(lines)-1, k+diffContext); c++ {
+			keep[c] = true
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString("--- golden\n+++ replay\n")
+	inHunk := false
+	for k, l := range lines {
+		if !keep[k] {
+			inHunk = false
+			continue
+		}
+		if !inHunk {
+			out.WriteString("@@\n")
+			inHunk = true
+		}
+		fmt.Fprintf(&out, "%c %s\n", l.op, l.text)
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,101 @@
+package replay
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	t.Run("内容相同时没有差异", func(t *testing.T) {
+		assert.Empty(t, Diff("a\nb\n", "a\nb\n"))
+	})
+
+	t.Run("修改的行带上下文", func(t *testing.T) {
+		want := "1\n2\n3\n4\n5\n6\n7\n"
+		got := "1\n2\n3\nfour\n5\n6\n7\n"
+		assert.Equal(t, "--- golden\n+++ replay\n@@\n  2\n  3\n- 4\n+ four\n  5\n  6\n", Diff(want, got))
+	})
+
+	t.Run("相隔较远的改动分段输出", func(t *testing.T) {
+		want := "a\nb\nc\nd\ne\nf\ng\nh\ni\n"
+		got := "A\nb\nc\nd\ne\nf\ng\nh\nI\n"
+		assert.Equal(t, "--- golden\n+++ replay\n@@\n- a\n+ A\n  b\n  c\n@@\n  g\n  h\n- i\n+ I\n", Diff(want, got))
+	})
+
+	t.Run("新增和删除的行", func(t *testing.T) {
+		assert.Equal(t, "--- golden\n+++ replay\n@@\n  a\n+ b\n  c\n", Diff("a\nc\n", "a\nb\nc\n"))
+		assert.Equal(t, "--- golden\n+++ replay\n@@\n  a\n- b\n  c\n", Diff("a\nb\nc\n", "a\nc\n"))
+		assert.Equal(t, "--- golden\n+++ replay\n@@\n+ x\n", Diff("", "x\n"))
+	})
+}
+
+func TestMarshal(t *testing.T) {
+	data, err := Marshal(Record{File: "a.eml", Subject: "<b>&</b>", Attachments: []Attachment{}})
+	require.NoError(t, err)
+	// 键按字母排序，HTML 字符不转义
+	assert.Equal(t, `{
+  "attachments": [],
+  "file": "a.eml",
+  "html": {
+    "length": 0
+  },
+  "preview": "",
+  "sanitizedHtml": {
+    "length": 0
+  },
+  "subject": "<b>&</b>",
+  "text": {
+    "length": 0
+  },
+  "textDerivedFromHtml": false
+}
+`, string(data))
+}
+
+func TestGolden(t *testing.T) {
+	records := []Record{{File: "a.eml", Subject: "first"}, {File: "nested/b.eml", Subject: "second"}}
+
+	t.Run("写入后比较一致", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, WriteGolden(dir, records))
+		assert.FileExists(t, filepath.Join(dir, "nested__b.json"))
+
+		mismatches, err := CompareGolden(dir, records)
+		require.NoError(t, err)
+		assert.Empty(t, mismatches)
+	})
+
+	t.Run("内容变化、缺少基准和多余基准都报告", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, WriteGolden(dir, records))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "stale.json"), []byte("{}\n"), 0o644))
+
+		changed := []Record{{File: "a.eml", Subject: "changed"}, {File: "c.eml"}}
+		mismatches, err := CompareGolden(dir, changed)
+		require.NoError(t, err)
+		require.Len(t, mismatches, 4)
+
+		assert.Equal(t, "a.eml", mismatches[0].File)
+		assert.Contains(t, mismatches[0].Diff, `-   "subject": "first",`)
+		assert.Contains(t, mismatches[0].Diff, `+   "subject": "changed",`)
+		assert.Equal(t, "c.eml", mismatches[1].File)
+		assert.Contains(t, mismatches[1].Diff, "缺少基准结果 c.json")
+		assert.Equal(t, "nested__b.json", mismatches[2].File)
+		assert.Equal(t, "stale.json", mismatches[3].File)
+	})
+
+	t.Run("更新时删除多余基准", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, WriteGolden(dir, records))
+		require.NoError(t, WriteGolden(dir, records[:1]))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "a.json", entries[0].Name())
+	})
+}
@@ -0,0 +1,277 @@
+// Package replay 将一批原始邮件按 SMTP 入库流程重新解析，输出可比较的归一化结果。
+//
+// 用于回归检查解析器改动：邮件经与 SMTP 会话相同的 MAIL → RCPT → DATA 流程写入内存存储，
+// 再读出解析、清洗后的字段。时钟固定、附件 ID 由内容哈希派生，同一封邮件每次输出完全相同。
+package replay
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/redact"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/smtp"
+	"tempmail/backend/internal/storage/memory"
+)
+
+const (
+	// Sender 回放时的信封发件人
+	Sender = "replay@sender.test"
+	// Recipient 回放邮件投递到的邮箱地址
+	Recipient = "inbox@replay.test"
+
+	replayDomain  = "replay.test"
+	replayMailbox = "replay-inbox"
+)
+
+// Clock 回放使用的固定时间
+var Clock = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// capturedHeaders 与默认配置一致的头名单
+var capturedHeaders = []string{"X-Test-Run-ID", "X-Correlation-ID", "List-Unsubscribe"}
+
+// Fixture 一封待回放的原始邮件
+type Fixture struct {
+	Name string // 相对路径（目录）或压缩包内路径
+	Raw  []byte
+}
+
+// Record 一封邮件入库后的归一化结果
+type Record struct {
+	File    string `json:"file"`
+	Error   string `json:"error,omitempty"` // 入库被拒绝时的错误（解析失败等），其余字段为空
+	From    string `json:"from,omitempty"`  // 邮件头 From
+	To      string `json:"to,omitempty"`    // 邮件头 To
+	Subject string `json:"subject,omitempty"`
+
+	Text          Body         `json:"text"`
+	HTML          Body         `json:"html"`
+	SanitizedHTML Body         `json:"sanitizedHtml"` // 公开收件箱展示的清洗结果
+	Attachments   []Attachment `json:"attachments"`
+
+	TextDerivedFromHTML bool              `json:"textDerivedFromHtml"`
+	Preview             string            `json:"preview"`
+	Language            string            `json:"language,omitempty"`
+	CapturedHeaders     map[string]string `json:"capturedHeaders,omitempty"`
+}
+
+// Body 正文的长度和哈希
+type Body struct {
+	Length int    `json:"length"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Attachment 附件摘要
+type Attachment struct {
+	ID                  string `json:"id"`
+	Filename            string `json:"filename"`
+	ContentType         string `json:"contentType"`
+	DetectedContentType string `json:"detectedContentType,omitempty"`
+	ContentTypeMismatch bool   `json:"contentTypeMismatch,omitempty"`
+	Size                int64  `json:"size"`
+	SHA256              string `json:"sha256"`
+}
+
+// Replayer 基于内存存储的回放器，不写入任何持久化存储
+type Replayer struct {
+	store    *memory.Store
+	messages *service.MessageService
+	backend  *smtp.Backend
+}
+
+// New 创建回放器
+func New() (*Replayer, error) {
+	store := memory.NewStore(0)
+	cfg := &config.Config{}
+
+	if err := store.SaveSystemDomain(context.Background(), &domain.SystemDomain{
+		ID: "sd-replay", Domain: replayDomain, Status: domain.SystemDomainStatusVerified,
+		IsActive: true, CreatedAt: Clock,
+	}); err != nil {
+		return nil, err
+	}
+	if err := store.SaveMailbox(context.Background(), &domain.Mailbox{
+		ID: replayMailbox, Address: Recipient, LocalPart: "inbox", Domain: replayDomain, CreatedAt: Clock,
+	}); err != nil {
+		return nil, err
+	}
+
+	messages := service.NewMessageService(store)
+	messages.SetClock(func() time.Time { return Clock })
+	backend := smtp.NewBackend(
+		service.NewMailboxService(store, store, cfg),
+		messages, nil,
+		service.NewSystemDomainService(store, cfg),
+		nil, nil, nil,
+	)
+	backend.SetStableAttachmentIDs(true)
+	backend.SetHeaderAllowlist(staticHeaders(capturedHeaders))
+
+	return &Replayer{store: store, messages: messages, backend: backend}, nil
+}
+
+// staticHeaders 固定的头名单
+type staticHeaders []string
+
+func (h staticHeaders) CapturedHeaders() []string { return h }
+
+// Replay 回放一封邮件；入库失败记入 Record.Error，只有存储异常才返回错误
+func (r *Replayer) Replay(ctx context.Context, fixture Fixture) (Record, error) {
+	record := Record{File: fixture.Name}
+	if err := r.backend.Inject(ctx, Sender, []string{Recipient}, fixture.Raw); err != nil {
+		record.Error = err.Error()
+		return record, nil
+	}
+	defer r.store.DeleteAllMessages(ctx, replayMailbox)
+
+	listed, err := r.store.ListMessages(ctx, replayMailbox)
+	if err != nil {
+		return record, err
+	}
+	if len(listed) != 1 {
+		return record, fmt.Errorf("%s: expected 1 stored message, got %d", fixture.Name, len(listed))
+	}
+	message, err := r.messages.Get(ctx, replayMailbox, listed[0].ID)
+	if err != nil {
+		return record, err
+	}
+
+	// 信封地址是固定值，From/To 取解析出的邮件头
+	if parsed, err := smtp.ParseEmail(fixture.Raw); err == nil {
+		record.From, record.To = parsed.From, parsed.To
+	}
+	record.Subject = message.Subject
+	record.Text = digestBody(message.Text)
+	record.HTML = digestBody(message.HTML)
+	if message.HTML != "" {
+		sanitized, err := redact.SanitizeHTML(message.HTML)
+		if err != nil {
+			return record, err
+		}
+		record.SanitizedHTML = digestBody(sanitized)
+	}
+	record.Attachments = make([]Attachment, 0, len(message.Attachments))
+	for _, att := range message.Attachments {
+		digest := att.SHA256
+		if digest == "" {
+			digest = hashHex(att.Content)
+		}
+		record.Attachments = append(record.Attachments, Attachment{
+			ID:                  att.ID,
+			Filename:            att.Filename,
+			ContentType:         att.ContentType,
+			DetectedContentType: att.DetectedContentType,
+			ContentTypeMismatch: att.ContentTypeMismatch,
+			Size:                att.Size,
+			SHA256:              digest,
+		})
+	}
+	record.TextDerivedFromHTML = message.TextDerivedFromHTML
+	record.Preview = service.Preview(message)
+	record.Language = message.DetectedLanguage
+	record.CapturedHeaders = message.CapturedHeaders
+	return record, nil
+}
+
+// ReplayAll 按顺序回放全部邮件
+func (r *Replayer) ReplayAll(ctx context.Context, fixtures []Fixture) ([]Record, error) {
+	records := make([]Record, 0, len(fixtures))
+	for _, fixture := range fixtures {
+		record, err := r.Replay(ctx, fixture)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func digestBody(body string) Body {
+	if body == "" {
+		return Body{}
+	}
+	return Body{Length: len(body), SHA256: hashHex([]byte(body))}
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Load 读取目录下的全部 .eml（含子目录）或压缩包中的 .eml 条目，按名称排序
+func Load(path string) ([]Fixture, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []Fixture
+	if info.IsDir() {
+		fixtures, err = loadDir(path)
+	} else {
+		fixtures, err = loadArchive(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(fixtures, func(i, j int) bool { return fixtures[i].Name < fixtures[j].Name })
+	return fixtures, nil
+}
+
+func loadDir(root string) ([]Fixture, error) {
+	var fixtures []Fixture
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.EqualFold(filepath.Ext(path), ".eml") {
+			return err
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, Fixture{Name: filepath.ToSlash(rel), Raw: raw})
+		return nil
+	})
+	return fixtures, err
+}
+
+// loadArchive 读取 zip 压缩包（例如打包的邮箱存储目录，每封邮件为 raw.eml）
+func loadArchive(path string) ([]Fixture, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer archive.Close()
+
+	var fixtures []Fixture
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || !strings.EqualFold(filepath.Ext(file.Name), ".eml") {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", file.Name, err)
+		}
+		fixtures = append(fixtures, Fixture{Name: file.Name, Raw: raw})
+	}
+	return fixtures, nil
+}
@@ -0,0 +1,77 @@
+package replay
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 语料回归：解析器改动导致结果变化时失败，确认是预期变化后执行
+//
+//	go run ./cmd/replay -golden internal/replay/testdata/golden -update internal/replay/testdata/corpus
+func TestCorpus(t *testing.T) {
+	fixtures, err := Load(filepath.Join("testdata", "corpus"))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(fixtures), 20)
+
+	replayer, err := New()
+	require.NoError(t, err)
+	records, err := replayer.ReplayAll(t.Context(), fixtures)
+	require.NoError(t, err)
+
+	mismatches, err := CompareGolden(filepath.Join("testdata", "golden"), records)
+	require.NoError(t, err)
+	for _, m := range mismatches {
+		t.Errorf("%s\n%s", m.File, m.Diff)
+	}
+
+	t.Run("重复回放结果相同", func(t *testing.T) {
+		again, err := New()
+		require.NoError(t, err)
+		repeated, err := again.ReplayAll(t.Context(), fixtures)
+		require.NoError(t, err)
+		assert.Equal(t, records, repeated)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	raw := []byte("From: a@example.com\r\nSubject: hi\r\n\r\nbody\r\n")
+
+	t.Run("读取压缩包中的 .eml 条目", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mailbox.zip")
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		zw := zip.NewWriter(f)
+		for _, name := range []string{"mb/m2/raw.eml", "mb/m1/raw.eml", "mb/m1/metadata.json"} {
+			w, err := zw.Create(name)
+			require.NoError(t, err)
+			_, err = w.Write(raw)
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+		require.NoError(t, f.Close())
+
+		fixtures, err := Load(path)
+		require.NoError(t, err)
+		require.Len(t, fixtures, 2)
+		assert.Equal(t, "mb/m1/raw.eml", fixtures[0].Name)
+		assert.Equal(t, "mb/m2/raw.eml", fixtures[1].Name)
+		assert.Equal(t, raw, fixtures[0].Raw)
+	})
+
+	t.Run("子目录中的邮件名使用相对路径", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "a.eml"), raw, 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), raw, 0o644))
+
+		fixtures, err := Load(dir)
+		require.NoError(t, err)
+		require.Len(t, fixtures, 1)
+		assert.Equal(t, "nested/a.eml", fixtures[0].Name)
+	})
+}
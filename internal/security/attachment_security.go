@@ -18,6 +18,9 @@ type AttachmentSecurity struct {
 
 	// 危险文件扩展名
 	dangerousExtensions map[string]bool
+
+	// 危险的实际类型（按内容判断，对应危险扩展名，防止改名绕过）
+	dangerousTypes map[string]bool
 }
 
 // NewAttachmentSecurity 创建附件安全检查器
@@ -51,6 +54,18 @@ func NewAttachmentSecurity() *AttachmentSecurity {
 			".asp": true,
 			".jsp": true,
 		},
+		dangerousTypes: map[string]bool{
+			"application/x-msdownload":  true,
+			"application/x-executable":  true,
+			"application/x-mach-binary": true,
+			"application/x-msi":         true,
+			"application/java-archive":  true,
+			"text/javascript":           true,
+			"text/vbscript":             true,
+			"text/x-msdos-batch":        true,
+			"text/x-shellscript":        true,
+			"text/x-powershell":         true,
+		},
 	}
 }
 
@@ -61,6 +76,15 @@ func (as *AttachmentSecurity) CheckAttachment(filename string, content io.Reader
 		return false, reason
 	}
 
+	// 按内容判断实际类型，文件名和声明类型都可能是伪装的
+	head := make([]byte, SniffLength)
+	n, _ := io.ReadFull(content, head)
+	head = head[:n]
+	content = io.MultiReader(bytes.NewReader(head), content)
+	if detected := SniffContentType(filename, head); as.dangerousTypes[detected] {
+		return false, "Dangerous file content: " + detected
+	}
+
 	// 检查 MIME 类型
 	if allowed, reason := as.checkMimeType(mimeType); !allowed {
 		return false, reason
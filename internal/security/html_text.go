@@ -0,0 +1,73 @@
+package security
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlSkipTags 不输出文本的标签
+var htmlSkipTags = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"head":     true,
+	"noscript": true,
+	"template": true,
+}
+
+// htmlBlockTags 块级标签（前后换行）
+var htmlBlockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"blockquote": true, "section": true, "article": true, "header": true, "footer": true,
+}
+
+// ExtractText 提取 HTML 中的可见文本
+//
+// 丢弃脚本、样式等不可见内容，块级元素转换为换行，连续空白折叠为单个空格。
+func ExtractText(htmlContent string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(htmlContent))
+
+	var b strings.Builder
+	skipDepth := 0
+	newline := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+	}
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.TrimSpace(b.String())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if htmlSkipTags[tag] {
+				skipDepth++
+			} else if htmlBlockTags[tag] {
+				newline()
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if htmlSkipTags[tag] && skipDepth > 0 {
+				skipDepth--
+			} else if htmlBlockTags[tag] {
+				newline()
+			}
+		case html.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			text := strings.Join(strings.Fields(string(tokenizer.Text())), " ")
+			if text == "" {
+				continue
+			}
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+				b.WriteByte(' ')
+			}
+			b.WriteString(text)
+		}
+	}
+}
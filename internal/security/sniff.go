@@ -0,0 +1,146 @@
+package security
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// SniffLength 判断附件类型需要读取的内容长度
+const SniffLength = 512
+
+// 可执行文件和常见容器格式的魔数（http.DetectContentType 不识别的部分）
+var magicTypes = []struct {
+	prefix      []byte
+	contentType string
+}{
+	{[]byte("MZ"), "application/x-msdownload"},
+	{[]byte("\x7fELF"), "application/x-executable"},
+	{[]byte{0xFE, 0xED, 0xFA, 0xCE}, "application/x-mach-binary"},
+	{[]byte{0xFE, 0xED, 0xFA, 0xCF}, "application/x-mach-binary"},
+	{[]byte{0xCE, 0xFA, 0xED, 0xFE}, "application/x-mach-binary"},
+	{[]byte{0xCF, 0xFA, 0xED, 0xFE}, "application/x-mach-binary"},
+	{[]byte("#!"), "text/x-shellscript"},
+	{[]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}, "application/x-ole-storage"},
+	{[]byte{0x37, 0x7A, 0xBC, 0xAF, 0x27, 0x1C}, "application/x-7z-compressed"},
+}
+
+// 同一容器格式按扩展名细分（ZIP 打包的 Office 文档、OLE 格式的旧版 Office 文档等）
+var containerSubtypes = map[string]map[string]string{
+	"application/zip": {
+		".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		".odt":  "application/vnd.oasis.opendocument.text",
+		".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+		".epub": "application/epub+zip",
+		".jar":  "application/java-archive",
+		".apk":  "application/vnd.android.package-archive",
+	},
+	"application/x-ole-storage": {
+		".doc": "application/msword",
+		".xls": "application/vnd.ms-excel",
+		".ppt": "application/vnd.ms-powerpoint",
+		".msg": "application/vnd.ms-outlook",
+		".msi": "application/x-msi",
+	},
+	"text/plain": {
+		".csv":  "text/csv",
+		".js":   "text/javascript",
+		".mjs":  "text/javascript",
+		".vbs":  "text/vbscript",
+		".ps1":  "text/x-powershell",
+		".sh":   "text/x-shellscript",
+		".bat":  "text/x-msdos-batch",
+		".cmd":  "text/x-msdos-batch",
+		".hta":  "text/html",
+		".svg":  "image/svg+xml",
+		".json": "application/json",
+	},
+}
+
+// 浏览器直接打开可能执行代码的类型
+var activeTypes = map[string]bool{
+	"text/html":                               true,
+	"application/xhtml+xml":                   true,
+	"image/svg+xml":                           true,
+	"text/xml":                                true,
+	"application/xml":                         true,
+	"text/javascript":                         true,
+	"application/javascript":                  true,
+	"text/vbscript":                           true,
+	"text/x-powershell":                       true,
+	"text/x-shellscript":                      true,
+	"text/x-msdos-batch":                      true,
+	"application/x-msdownload":                true,
+	"application/x-executable":                true,
+	"application/x-mach-binary":               true,
+	"application/x-msi":                       true,
+	"application/java-archive":                true,
+	"application/vnd.android.package-archive": true,
+}
+
+// SniffContentType 根据内容开头的魔数判断附件的实际类型（只返回媒体类型，不含参数）
+//
+// 先匹配可执行文件等 http.DetectContentType 不识别的格式，再用文件扩展名细分容器格式
+// （如 ZIP 打包的 docx）和纯文本（如 .js 脚本）。无法判断时返回 application/octet-stream。
+func SniffContentType(filename string, head []byte) string {
+	if len(head) > SniffLength {
+		head = head[:SniffLength]
+	}
+	detected := ""
+	for _, magic := range magicTypes {
+		if bytes.HasPrefix(head, magic.prefix) {
+			detected = magic.contentType
+			break
+		}
+	}
+	if detected == "" {
+		detected = mediaType(http.DetectContentType(head))
+	}
+	if detected == "text/plain" && bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+		detected = "image/svg+xml"
+	}
+	if subtypes, ok := containerSubtypes[detected]; ok {
+		if subtype, ok := subtypes[strings.ToLower(filepath.Ext(filename))]; ok {
+			detected = subtype
+		}
+	}
+	return detected
+}
+
+// ClassifyAttachment 判断附件的实际类型，并检查与声明类型是否存在安全相关的不一致
+func ClassifyAttachment(filename, declared string, head []byte) (detected string, mismatch bool) {
+	detected = SniffContentType(filename, head)
+	return detected, ContentTypeMismatch(declared, detected)
+}
+
+// IsActiveContentType 浏览器打开时可能执行脚本或程序的类型（下载时不能按该类型返回）
+func IsActiveContentType(contentType string) bool {
+	return activeTypes[mediaType(contentType)]
+}
+
+// ContentTypeMismatch 声明类型与实际类型是否存在安全相关的不一致：
+// 实际为可执行文件、脚本或 HTML，而声明的是其他类型（如伪装成图片）
+func ContentTypeMismatch(declared, detected string) bool {
+	declared, detected = mediaType(declared), mediaType(detected)
+	if declared == detected || !IsActiveContentType(detected) {
+		return false
+	}
+	// 未声明或声明为通用二进制不算伪装
+	if declared == "" || declared == "application/octet-stream" {
+		return false
+	}
+	return !IsActiveContentType(declared)
+}
+
+// mediaType 去掉参数并转小写，无法解析时原样返回小写值
+func mediaType(contentType string) string {
+	parsed, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return parsed
+}
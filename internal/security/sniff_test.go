@@ -0,0 +1,51 @@
+package security
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyAttachment(t *testing.T) {
+	t.Run("声明为通用二进制的 PDF 识别为 PDF", func(t *testing.T) {
+		detected, mismatch := ClassifyAttachment("report.pdf", "application/octet-stream", []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"))
+		assert.Equal(t, "application/pdf", detected)
+		assert.False(t, mismatch)
+	})
+
+	t.Run("伪装成图片的 HTML", func(t *testing.T) {
+		detected, mismatch := ClassifyAttachment("photo.png", "image/png", []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"))
+		assert.Equal(t, "text/html", detected)
+		assert.True(t, mismatch)
+	})
+
+	t.Run("伪装成图片的可执行文件", func(t *testing.T) {
+		detected, mismatch := ClassifyAttachment("photo.jpg", "image/jpeg", append([]byte("MZ\x90\x00"), make([]byte, 60)...))
+		assert.Equal(t, "application/x-msdownload", detected)
+		assert.True(t, mismatch)
+	})
+
+	t.Run("ZIP 格式按扩展名识别为 docx", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		f, err := w.Create("word/document.xml")
+		require.NoError(t, err)
+		_, err = f.Write([]byte("<w:document/>"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		detected, mismatch := ClassifyAttachment("Contract.DOCX", "application/octet-stream", buf.Bytes())
+		assert.Equal(t, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", detected)
+		assert.False(t, mismatch)
+	})
+
+	t.Run("声明与实际一致或声明本身即为活动类型不算伪装", func(t *testing.T) {
+		_, mismatch := ClassifyAttachment("page.html", "text/html; charset=utf-8", []byte("<html><body>hi</body></html>"))
+		assert.False(t, mismatch)
+		_, mismatch = ClassifyAttachment("notes.txt", "text/plain", []byte("just some notes"))
+		assert.False(t, mismatch)
+	})
+}
@@ -0,0 +1,87 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrUnsafeURL 回调地址不是公网 HTTP(S) 地址
+var ErrUnsafeURL = errors.New("url must be a public http(s) address")
+
+// ValidatePublicURL 校验回调地址：只允许 http/https、不带用户信息，
+// 主机不能是 localhost 或内网、回环、链路本地等非公网 IP。
+//
+// 域名在这里不解析（DNS 结果可能在投递时变化），连接时由 PublicDialer 再次检查实际地址。
+func ValidatePublicURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ErrUnsafeURL
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrUnsafeURL
+	}
+	if u.User != nil {
+		return ErrUnsafeURL
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrUnsafeURL
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+		return ErrUnsafeURL
+	}
+	return nil
+}
+
+// IsPublicIP 是否为公网单播地址
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		// 100.64.0.0/10 运营商级 NAT，0.0.0.0/8，255.255.255.255
+		if ip4[0] == 0 || (ip4[0] == 100 && ip4[1]&0xc0 == 64) || ip4.Equal(net.IPv4bcast) {
+			return false
+		}
+	}
+	return true
+}
+
+// PublicDialer 只允许连接公网地址的拨号器（在解析后的实际地址上检查，防止 DNS 重绑定）
+func PublicDialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !IsPublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrUnsafeURL, host)
+			}
+			return nil
+		},
+	}
+}
+
+// NewPublicHTTPClient 创建只能访问公网地址的 HTTP 客户端（不走代理）
+func NewPublicHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         PublicDialer(timeout).DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
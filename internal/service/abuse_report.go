@@ -0,0 +1,449 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/monitoring"
+	"tempmail/backend/internal/storage"
+)
+
+var (
+	ErrAbuseTargetInvalid    = errors.New("abuse report target must be a mailbox address or message url")
+	ErrAbuseCategoryInvalid  = errors.New("invalid abuse report category")
+	ErrAbuseDetailsInvalid   = errors.New("abuse report details are required and limited in length")
+	ErrAbuseReporterInvalid  = errors.New("invalid reporter email")
+	ErrAbuseReportNotFound   = errors.New("abuse report not found")
+	ErrAbuseReportTransition = errors.New("abuse report cannot move to this status")
+	ErrAbuseActionInvalid    = errors.New("invalid abuse report action")
+	ErrAbuseReportUnlinked   = errors.New("abuse report is not linked to a mailbox or message")
+)
+
+// 举报内容长度上限
+const (
+	MaxAbuseTargetLength  = 512
+	MaxAbuseDetailsLength = 2000 // 按字符计
+	// AbuseEscalationThreshold 同一邮箱未处理的举报来自这么多不同举报人时标记为优先处理并告警
+	AbuseEscalationThreshold = 3
+)
+
+// 滥用举报审计事件
+const (
+	AuditAbuseReportTriaged  = "abuse_report_triaged"
+	AuditAbuseReportResolved = "abuse_report_resolved"
+	AuditMailboxSuspended    = "mailbox_suspended"
+	AuditMailboxUnsuspended  = "mailbox_unsuspended"
+)
+
+// AbuseAuditFunc 举报处理审计回调；targetID 为受影响的邮箱或邮件
+type AbuseAuditFunc func(event, actorID, reportID, targetID string)
+
+// AbuseAlerter 发出升级告警（由 monitoring.AlertManager 实现）
+type AbuseAlerter interface {
+	TriggerAlert(alert *monitoring.Alert)
+}
+
+// MailboxSuspender 停用邮箱时断开实时订阅（由 WebSocket Hub 实现）
+type MailboxSuspender interface {
+	SuspendMailbox(mailboxID string)
+}
+
+// SetMailboxSuspender 设置停用通知（避免依赖 websocket 包）
+func (s *MailboxService) SetMailboxSuspender(suspender MailboxSuspender) {
+	s.suspender = suspender
+}
+
+// SetSuspended 停用或恢复邮箱（仅管理员调用）
+//
+// 停用立即生效：邮箱保存后缓存随之刷新，读取接口和 SMTP 投递都会拒绝，已建立的实时订阅被撤销。
+// 邮件和别名保留，恢复后照常使用。
+func (s *MailboxService) SetSuspended(ctx context.Context, id string, suspended bool) (*domain.Mailbox, error) {
+	mailbox, err := s.repo.GetMailbox(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if mailbox.Suspended == suspended {
+		return mailbox, nil
+	}
+
+	mailbox.Suspended = suspended
+	if err := s.repo.SaveMailbox(ctx, mailbox); err != nil {
+		return nil, err
+	}
+	if suspended && s.suspender != nil {
+		s.suspender.SuspendMailbox(id)
+	}
+	return mailbox, nil
+}
+
+// SubmitAbuseReportInput 公开提交举报的输入
+type SubmitAbuseReportInput struct {
+	ReporterEmail string // 可选
+	ReporterIP    string
+	Target        string // 邮箱地址或邮件链接
+	Category      domain.AbuseCategory
+	Details       string
+}
+
+// ListAbuseReportsInput 管理员列出举报的输入参数
+type ListAbuseReportsInput struct {
+	Filter   domain.AbuseReportFilter
+	Page     int
+	PageSize int
+}
+
+// ListAbuseReportsOutput 管理员列出举报的输出结果
+type ListAbuseReportsOutput struct {
+	Reports    []*domain.AbuseReport `json:"reports"`
+	Total      int                   `json:"total"`
+	Page       int                   `json:"page"`
+	PageSize   int                   `json:"pageSize"`
+	TotalPages int                   `json:"totalPages"`
+}
+
+// ResolveAbuseReportInput 处理举报的输入
+type ResolveAbuseReportInput struct {
+	Action domain.AbuseAction
+	Note   string
+}
+
+// AbuseReportService 滥用举报服务
+//
+// 第三方针对经由本实例投递的邮件提交举报，服务端把举报关联到对应的邮箱和邮件（举报人看不到任何
+// 邮件内容），管理员分拣后隔离邮件、停用邮箱或驳回。同一邮箱收到多个举报人的举报时自动升级。
+type AbuseReportService struct {
+	store     storage.Store
+	mailboxes *MailboxService
+	validator *domain.EmailValidator
+	audit     AbuseAuditFunc
+	alerter   AbuseAlerter
+	now       func() time.Time
+}
+
+// NewAbuseReportService 创建滥用举报服务
+func NewAbuseReportService(store storage.Store, mailboxes *MailboxService) *AbuseReportService {
+	return &AbuseReportService{
+		store:     store,
+		mailboxes: mailboxes,
+		validator: domain.NewEmailValidator(),
+		now:       time.Now,
+	}
+}
+
+// SetAuditFunc 设置审计回调
+func (s *AbuseReportService) SetAuditFunc(fn AbuseAuditFunc) {
+	s.audit = fn
+}
+
+// SetAlerter 设置升级告警
+func (s *AbuseReportService) SetAlerter(alerter AbuseAlerter) {
+	s.alerter = alerter
+}
+
+// SetClock 设置时钟（测试用）
+func (s *AbuseReportService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Submit 校验并保存举报，能解析到邮箱时关联邮箱和邮件
+//
+// 解析不到邮箱的举报同样保存（可能是已删除的邮箱），由管理员驳回。
+func (s *AbuseReportService) Submit(ctx context.Context, input SubmitAbuseReportInput) (*domain.AbuseReport, error) {
+	target := strings.TrimSpace(input.Target)
+	if target == "" || len(target) > MaxAbuseTargetLength {
+		return nil, ErrAbuseTargetInvalid
+	}
+	if !input.Category.Valid() {
+		return nil, ErrAbuseCategoryInvalid
+	}
+	details := strings.TrimSpace(input.Details)
+	if details == "" || !utf8.ValidString(details) || utf8.RuneCountInString(details) > MaxAbuseDetailsLength {
+		return nil, ErrAbuseDetailsInvalid
+	}
+	reporter := strings.ToLower(strings.TrimSpace(input.ReporterEmail))
+	if reporter != "" && s.validator.ValidateEmail(reporter) != nil {
+		return nil, ErrAbuseReporterInvalid
+	}
+
+	mailboxID, messageID, err := s.resolveTarget(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	report := &domain.AbuseReport{
+		ID:            uuid.NewString(),
+		ReporterEmail: reporter,
+		ReporterIP:    input.ReporterIP,
+		Target:        target,
+		Category:      input.Category,
+		Details:       details,
+		Status:        domain.AbuseReportStatusNew,
+		MailboxID:     mailboxID,
+		MessageID:     messageID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.store.SaveAbuseReport(ctx, report); err != nil {
+		return nil, err
+	}
+	if mailboxID != "" {
+		if err := s.escalate(ctx, report); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// resolveTarget 把举报目标解析为邮箱和邮件 ID
+//
+// 支持邮箱或别名地址，以及包含 /mailboxes/{id}/messages/{messageId} 或
+// /inboxes/{address}/messages/{messageId} 的链接。格式正确但找不到对应邮箱时返回空 ID。
+func (s *AbuseReportService) resolveTarget(ctx context.Context, target string) (mailboxID, messageID string, err error) {
+	if !strings.Contains(target, "/") {
+		if s.validator.ValidateEmail(target) != nil {
+			return "", "", ErrAbuseTargetInvalid
+		}
+		return s.resolveAddress(ctx, target), "", nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", "", ErrAbuseTargetInvalid
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+3 < len(segments); i++ {
+		if segments[i+2] != "messages" {
+			continue
+		}
+		switch segments[i] {
+		case "mailboxes":
+			mailboxID = segments[i+1]
+		case "inboxes":
+			mailboxID = s.resolveAddress(ctx, segments[i+1])
+		default:
+			continue
+		}
+		if mailboxID == "" {
+			return "", "", nil
+		}
+		if _, err := s.store.GetMailbox(ctx, mailboxID); err != nil {
+			return "", "", nil
+		}
+		messageID = segments[i+3]
+		if _, err := s.store.GetMessage(ctx, mailboxID, messageID); err != nil {
+			messageID = "" // 邮件已删除或链接有误，仍关联到邮箱
+		}
+		return mailboxID, messageID, nil
+	}
+	return "", "", ErrAbuseTargetInvalid
+}
+
+// resolveAddress 按邮箱地址或别名地址查找邮箱 ID，找不到时返回空
+func (s *AbuseReportService) resolveAddress(ctx context.Context, address string) string {
+	if address, err := url.PathUnescape(address); err == nil {
+		if mailbox, err := s.mailboxes.GetByAddress(ctx, address); err == nil {
+			return mailbox.ID
+		}
+		if alias, err := s.store.GetAliasByAddress(ctx, strings.ToLower(strings.TrimSpace(address))); err == nil {
+			return alias.MailboxID
+		}
+	}
+	return ""
+}
+
+// escalate 同一邮箱未处理的举报来自足够多的不同举报人（按 IP 区分，邮箱可随意填写）时，
+// 把这些举报标记为优先处理并发出告警（告警按邮箱去重，解决前不重复发送）
+func (s *AbuseReportService) escalate(ctx context.Context, report *domain.AbuseReport) error {
+	reports, err := s.store.ListAbuseReports(ctx, domain.AbuseReportFilter{MailboxID: report.MailboxID})
+	if err != nil {
+		return err
+	}
+	var open []*domain.AbuseReport
+	reporters := make(map[string]struct{})
+	for _, r := range reports {
+		if r.Status == domain.AbuseReportStatusNew || r.Status == domain.AbuseReportStatusTriaged {
+			open = append(open, r)
+			reporters[r.ReporterIP] = struct{}{}
+		}
+	}
+	if len(reporters) < AbuseEscalationThreshold {
+		return nil
+	}
+
+	for _, r := range open {
+		if r.Priority {
+			continue
+		}
+		r.Priority = true
+		if err := s.store.SaveAbuseReport(ctx, r); err != nil {
+			return err
+		}
+		if r.ID == report.ID {
+			report.Priority = true
+		}
+	}
+	if s.alerter != nil {
+		s.alerter.TriggerAlert(&monitoring.Alert{
+			ID:        "abuse-escalation-" + report.MailboxID,
+			Title:     "Mailbox reported for abuse",
+			Message:   fmt.Sprintf("mailbox %s has open abuse reports from %d reporters", report.MailboxID, len(reporters)),
+			Level:     monitoring.AlertLevelWarning,
+			Component: "abuse",
+			Timestamp: s.now(),
+			Metadata: map[string]interface{}{
+				"mailbox_id": report.MailboxID,
+				"reporters":  len(reporters),
+				"reports":    len(open),
+			},
+		})
+	}
+	return nil
+}
+
+// List 按过滤条件分页列出举报（新举报在前）
+func (s *AbuseReportService) List(ctx context.Context, input ListAbuseReportsInput) (*ListAbuseReportsOutput, error) {
+	if input.Page <= 0 {
+		input.Page = 1
+	}
+	if input.PageSize <= 0 {
+		input.PageSize = 20
+	}
+	if input.PageSize > 100 {
+		input.PageSize = 100
+	}
+
+	reports, err := s.store.ListAbuseReports(ctx, input.Filter)
+	if err != nil {
+		return nil, err
+	}
+	total := len(reports)
+	start := (input.Page - 1) * input.PageSize
+	if start > total {
+		start = total
+	}
+	end := start + input.PageSize
+	if end > total {
+		end = total
+	}
+
+	return &ListAbuseReportsOutput{
+		Reports:    reports[start:end],
+		Total:      total,
+		Page:       input.Page,
+		PageSize:   input.PageSize,
+		TotalPages: (total + input.PageSize - 1) / input.PageSize,
+	}, nil
+}
+
+// Get 获取举报
+func (s *AbuseReportService) Get(ctx context.Context, id string) (*domain.AbuseReport, error) {
+	report, err := s.store.GetAbuseReport(ctx, id)
+	if errors.Is(err, storage.ErrAbuseReportNotFound) {
+		return nil, ErrAbuseReportNotFound
+	}
+	return report, err
+}
+
+// Triage 确认举报待处理（new -> triaged）
+func (s *AbuseReportService) Triage(ctx context.Context, id, actorID, note string) (*domain.AbuseReport, error) {
+	report, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status != domain.AbuseReportStatusNew {
+		return nil, ErrAbuseReportTransition
+	}
+
+	report.Status = domain.AbuseReportStatusTriaged
+	if note = strings.TrimSpace(note); note != "" {
+		report.Note = note
+	}
+	report.UpdatedAt = s.now().UTC()
+	if err := s.store.SaveAbuseReport(ctx, report); err != nil {
+		return nil, err
+	}
+	s.record(AuditAbuseReportTriaged, actorID, report.ID, report.MailboxID)
+	return report, nil
+}
+
+// Resolve 处理举报：隔离邮件、停用邮箱（状态变为 actioned）或驳回（dismissed）
+//
+// 未处理（new/triaged）的举报才能处理；隔离需要举报关联到邮件，停用需要关联到邮箱。
+func (s *AbuseReportService) Resolve(ctx context.Context, id, actorID string, input ResolveAbuseReportInput) (*domain.AbuseReport, error) {
+	report, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status != domain.AbuseReportStatusNew && report.Status != domain.AbuseReportStatusTriaged {
+		return nil, ErrAbuseReportTransition
+	}
+
+	status := domain.AbuseReportStatusActioned
+	target := report.MailboxID
+	switch input.Action {
+	case domain.AbuseActionQuarantine:
+		if report.MessageID == "" {
+			return nil, ErrAbuseReportUnlinked
+		}
+		if err := s.store.SetMessageQuarantined(ctx, report.MailboxID, report.MessageID, true); err != nil {
+			return nil, err
+		}
+		target = report.MessageID
+	case domain.AbuseActionSuspend:
+		if report.MailboxID == "" {
+			return nil, ErrAbuseReportUnlinked
+		}
+		if _, err := s.mailboxes.SetSuspended(ctx, report.MailboxID, true); err != nil {
+			return nil, err
+		}
+		s.record(AuditMailboxSuspended, actorID, report.ID, report.MailboxID)
+	case domain.AbuseActionDismiss:
+		status = domain.AbuseReportStatusDismissed
+	default:
+		return nil, ErrAbuseActionInvalid
+	}
+
+	now := s.now().UTC()
+	report.Status = status
+	report.Action = input.Action
+	if note := strings.TrimSpace(input.Note); note != "" {
+		report.Note = note
+	}
+	report.ResolvedBy = actorID
+	report.ResolvedAt = &now
+	report.UpdatedAt = now
+	if err := s.store.SaveAbuseReport(ctx, report); err != nil {
+		return nil, err
+	}
+	s.record(AuditAbuseReportResolved, actorID, report.ID, target)
+	return report, nil
+}
+
+// SetMailboxSuspended 管理员直接停用或恢复邮箱（不经过举报）
+func (s *AbuseReportService) SetMailboxSuspended(ctx context.Context, mailboxID, actorID string, suspended bool) (*domain.Mailbox, error) {
+	mailbox, err := s.mailboxes.SetSuspended(ctx, mailboxID, suspended)
+	if err != nil {
+		return nil, err
+	}
+	event := AuditMailboxUnsuspended
+	if suspended {
+		event = AuditMailboxSuspended
+	}
+	s.record(event, actorID, "", mailboxID)
+	return mailbox, nil
+}
+
+func (s *AbuseReportService) record(event, actorID, reportID, targetID string) {
+	if s.audit != nil {
+		s.audit(event, actorID, reportID, targetID)
+	}
+}
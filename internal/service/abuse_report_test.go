@@ -0,0 +1,238 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/monitoring"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// recordingSuspender 记录被停用的邮箱
+type recordingSuspender struct {
+	suspended []string
+}
+
+func (r *recordingSuspender) SuspendMailbox(mailboxID string) {
+	r.suspended = append(r.suspended, mailboxID)
+}
+
+type abuseFixture struct {
+	store     *memory.Store
+	mailboxes *MailboxService
+	messages  *MessageService
+	reports   *AbuseReportService
+	suspender *recordingSuspender
+	alerts    *monitoring.AlertManager
+	audit     []string
+	mailbox   *domain.Mailbox
+	message   *domain.Message
+}
+
+func newAbuseFixture(t *testing.T) *abuseFixture {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.example"}}}
+	f := &abuseFixture{
+		store:     store,
+		mailboxes: NewMailboxService(store, store, cfg),
+		messages:  NewMessageService(store),
+		suspender: &recordingSuspender{},
+		alerts:    monitoring.NewAlertManager(zap.NewNop()),
+	}
+	f.mailboxes.SetMailboxSuspender(f.suspender)
+	f.reports = NewAbuseReportService(store, f.mailboxes)
+	f.reports.SetAlerter(f.alerts)
+	f.reports.SetAuditFunc(func(event, actorID, reportID, targetID string) {
+		f.audit = append(f.audit, event+":"+actorID+":"+targetID)
+	})
+
+	var err error
+	f.mailbox, err = f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "scam", Domain: "temp.example"})
+	require.NoError(t, err)
+	f.message, err = f.messages.Create(t.Context(), CreateMessageInput{
+		MailboxID: f.mailbox.ID, From: "bank@phish.example", Subject: "Verify your account", Text: "click here",
+	})
+	require.NoError(t, err)
+	return f
+}
+
+func (f *abuseFixture) submit(t *testing.T, ip, target string) *domain.AbuseReport {
+	t.Helper()
+	report, err := f.reports.Submit(t.Context(), SubmitAbuseReportInput{
+		ReporterIP: ip, Target: target, Category: domain.AbuseCategoryPhishing, Details: "credential phishing",
+	})
+	require.NoError(t, err)
+	return report
+}
+
+func TestAbuseReportService_Submit(t *testing.T) {
+	f := newAbuseFixture(t)
+
+	t.Run("校验举报内容", func(t *testing.T) {
+		valid := SubmitAbuseReportInput{Target: f.mailbox.Address, Category: domain.AbuseCategorySpam, Details: "spam"}
+		cases := map[string]struct {
+			mutate func(in *SubmitAbuseReportInput)
+			want   error
+		}{
+			"缺少举报对象":     {func(in *SubmitAbuseReportInput) { in.Target = " " }, ErrAbuseTargetInvalid},
+			"举报对象过长":     {func(in *SubmitAbuseReportInput) { in.Target = strings.Repeat("a", MaxAbuseTargetLength) + "@x.example" }, ErrAbuseTargetInvalid},
+			"既不是地址也不是链接": {func(in *SubmitAbuseReportInput) { in.Target = "not an address" }, ErrAbuseTargetInvalid},
+			"链接中没有邮件路径":  {func(in *SubmitAbuseReportInput) { in.Target = "https://mail.example/about" }, ErrAbuseTargetInvalid},
+			"未知类别":       {func(in *SubmitAbuseReportInput) { in.Category = "malware" }, ErrAbuseCategoryInvalid},
+			"缺少说明":       {func(in *SubmitAbuseReportInput) { in.Details = "  " }, ErrAbuseDetailsInvalid},
+			"说明过长":       {func(in *SubmitAbuseReportInput) { in.Details = strings.Repeat("垃", MaxAbuseDetailsLength+1) }, ErrAbuseDetailsInvalid},
+			"举报人邮箱无效":    {func(in *SubmitAbuseReportInput) { in.ReporterEmail = "reporter@" }, ErrAbuseReporterInvalid},
+		}
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				input := valid
+				tc.mutate(&input)
+				_, err := f.reports.Submit(t.Context(), input)
+				assert.ErrorIs(t, err, tc.want)
+			})
+		}
+
+		input := valid
+		input.Details = strings.Repeat("垃", MaxAbuseDetailsLength) // 按字符计
+		_, err := f.reports.Submit(t.Context(), input)
+		assert.NoError(t, err)
+	})
+
+	t.Run("关联到邮箱和邮件", func(t *testing.T) {
+		require.NoError(t, f.store.SaveAlias(t.Context(), &domain.MailboxAlias{
+			ID: "alias-1", MailboxID: f.mailbox.ID, Address: "offers@temp.example", IsActive: true,
+		}))
+
+		byAddress := f.submit(t, "198.51.100.1", strings.ToUpper(f.mailbox.Address))
+		assert.Equal(t, f.mailbox.ID, byAddress.MailboxID)
+		assert.Empty(t, byAddress.MessageID)
+		assert.Equal(t, domain.AbuseReportStatusNew, byAddress.Status)
+
+		byAlias := f.submit(t, "198.51.100.1", "offers@temp.example")
+		assert.Equal(t, f.mailbox.ID, byAlias.MailboxID)
+
+		byURL := f.submit(t, "198.51.100.1", "https://mail.example/mailboxes/"+f.mailbox.ID+"/messages/"+f.message.ID)
+		assert.Equal(t, f.mailbox.ID, byURL.MailboxID)
+		assert.Equal(t, f.message.ID, byURL.MessageID)
+
+		byInboxURL := f.submit(t, "198.51.100.1", "https://mail.example/v1/public/inboxes/"+f.mailbox.Address+"/messages/"+f.message.ID)
+		assert.Equal(t, f.message.ID, byInboxURL.MessageID)
+
+		unknown := f.submit(t, "198.51.100.1", "nobody@temp.example")
+		assert.Empty(t, unknown.MailboxID, "找不到邮箱时仍保存举报")
+		stored, err := f.reports.Get(t.Context(), unknown.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "nobody@temp.example", stored.Target)
+	})
+}
+
+func TestAbuseReportService_Workflow(t *testing.T) {
+	t.Run("分拣后隔离邮件", func(t *testing.T) {
+		f := newAbuseFixture(t)
+		report := f.submit(t, "198.51.100.1", "https://mail.example/mailboxes/"+f.mailbox.ID+"/messages/"+f.message.ID)
+
+		triaged, err := f.reports.Triage(t.Context(), report.ID, "admin-1", "looks like phishing")
+		require.NoError(t, err)
+		assert.Equal(t, domain.AbuseReportStatusTriaged, triaged.Status)
+		_, err = f.reports.Triage(t.Context(), report.ID, "admin-1", "")
+		assert.ErrorIs(t, err, ErrAbuseReportTransition)
+
+		resolved, err := f.reports.Resolve(t.Context(), report.ID, "admin-1", ResolveAbuseReportInput{Action: domain.AbuseActionQuarantine})
+		require.NoError(t, err)
+		assert.Equal(t, domain.AbuseReportStatusActioned, resolved.Status)
+		assert.Equal(t, "admin-1", resolved.ResolvedBy)
+		assert.NotNil(t, resolved.ResolvedAt)
+		assert.Equal(t, "looks like phishing", resolved.Note)
+
+		inbox, err := f.messages.List(t.Context(), f.mailbox.ID)
+		require.NoError(t, err)
+		assert.Empty(t, inbox)
+		quarantined, err := f.messages.ListQuarantined(t.Context(), f.mailbox.ID)
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1)
+
+		_, err = f.reports.Resolve(t.Context(), report.ID, "admin-1", ResolveAbuseReportInput{Action: domain.AbuseActionDismiss})
+		assert.ErrorIs(t, err, ErrAbuseReportTransition, "已处理的举报不能再处理")
+		assert.Equal(t, []string{
+			AuditAbuseReportTriaged + ":admin-1:" + f.mailbox.ID,
+			AuditAbuseReportResolved + ":admin-1:" + f.message.ID,
+		}, f.audit)
+	})
+
+	t.Run("驳回和无效处置", func(t *testing.T) {
+		f := newAbuseFixture(t)
+		unlinked := f.submit(t, "198.51.100.1", "nobody@temp.example")
+
+		_, err := f.reports.Resolve(t.Context(), unlinked.ID, "admin-1", ResolveAbuseReportInput{Action: domain.AbuseActionSuspend})
+		assert.ErrorIs(t, err, ErrAbuseReportUnlinked)
+		_, err = f.reports.Resolve(t.Context(), unlinked.ID, "admin-1", ResolveAbuseReportInput{Action: "delete"})
+		assert.ErrorIs(t, err, ErrAbuseActionInvalid)
+
+		dismissed, err := f.reports.Resolve(t.Context(), unlinked.ID, "admin-1", ResolveAbuseReportInput{Action: domain.AbuseActionDismiss, Note: "no such mailbox"})
+		require.NoError(t, err)
+		assert.Equal(t, domain.AbuseReportStatusDismissed, dismissed.Status)
+
+		_, err = f.reports.Triage(t.Context(), "missing", "admin-1", "")
+		assert.ErrorIs(t, err, ErrAbuseReportNotFound)
+	})
+
+	t.Run("停用邮箱", func(t *testing.T) {
+		f := newAbuseFixture(t)
+		report := f.submit(t, "198.51.100.1", f.mailbox.Address)
+
+		_, err := f.reports.Resolve(t.Context(), report.ID, "admin-1", ResolveAbuseReportInput{Action: domain.AbuseActionSuspend})
+		require.NoError(t, err)
+		mailbox, err := f.mailboxes.Get(t.Context(), f.mailbox.ID)
+		require.NoError(t, err)
+		assert.True(t, mailbox.Suspended)
+		assert.Equal(t, []string{f.mailbox.ID}, f.suspender.suspended, "实时订阅立即撤销")
+		assert.Contains(t, f.audit, AuditMailboxSuspended+":admin-1:"+f.mailbox.ID)
+
+		mailbox, err = f.reports.SetMailboxSuspended(t.Context(), f.mailbox.ID, "admin-2", false)
+		require.NoError(t, err)
+		assert.False(t, mailbox.Suspended)
+		assert.Contains(t, f.audit, AuditMailboxUnsuspended+":admin-2:"+f.mailbox.ID)
+		assert.Len(t, f.suspender.suspended, 1, "恢复时不撤销订阅")
+	})
+}
+
+func TestAbuseReportService_Escalation(t *testing.T) {
+	f := newAbuseFixture(t)
+
+	first := f.submit(t, "198.51.100.1", f.mailbox.Address)
+	f.submit(t, "198.51.100.1", f.mailbox.Address) // 同一举报人重复举报不计数
+	second := f.submit(t, "198.51.100.2", f.mailbox.Address)
+	assert.False(t, second.Priority)
+	assert.Empty(t, f.alerts.GetActiveAlerts())
+
+	third := f.submit(t, "198.51.100.3", f.mailbox.Address)
+	assert.True(t, third.Priority)
+
+	t.Run("未处理的举报都标记为优先处理", func(t *testing.T) {
+		stored, err := f.reports.Get(t.Context(), first.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.Priority)
+
+		priority, err := f.reports.List(t.Context(), ListAbuseReportsInput{Filter: domain.AbuseReportFilter{Priority: true}})
+		require.NoError(t, err)
+		assert.Equal(t, 4, priority.Total)
+	})
+
+	t.Run("按邮箱发出一次告警", func(t *testing.T) {
+		f.submit(t, "198.51.100.4", f.mailbox.Address)
+
+		alerts := f.alerts.GetActiveAlerts()
+		require.Len(t, alerts, 1)
+		assert.Equal(t, "abuse-escalation-"+f.mailbox.ID, alerts[0].ID)
+		assert.Equal(t, monitoring.AlertLevelWarning, alerts[0].Level)
+		assert.Equal(t, f.mailbox.ID, alerts[0].Metadata["mailbox_id"])
+	})
+}
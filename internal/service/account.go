@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/auth"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/mailfmt"
+	"tempmail/backend/internal/storage"
+)
+
+const (
+	// EmailCodeTTL 邮箱验证码有效期
+	EmailCodeTTL = 30 * time.Minute
+	// EmailCodeResendInterval 两次发送验证码的最小间隔
+	EmailCodeResendInterval = time.Minute
+	// MaxEmailVerifyAttempts 每个验证码允许的尝试次数，用尽后须重新发送
+	MaxEmailVerifyAttempts = 5
+)
+
+var (
+	ErrEmailVerificationUnavailable = errors.New("email verification is not available")
+	ErrEmailAlreadyVerified         = errors.New("email is already verified")
+	ErrEmailCodeCooldown            = errors.New("verification code was sent recently, try again later")
+	ErrEmailCodeInvalid             = errors.New("verification code is invalid")
+	ErrEmailCodeExpired             = errors.New("verification code has expired, request a new one")
+	ErrAccountConfirmMismatch       = errors.New("confirmation does not match the account email")
+	ErrAccountIsSuper               = errors.New("super admin accounts cannot be deleted")
+	ErrAccountOwnsOrganization      = errors.New("transfer or delete your organizations before deleting the account")
+)
+
+// AccountCodeStore 保存邮箱验证码及其尝试次数（由缓存实现）
+type AccountCodeStore interface {
+	storage.SessionRepository
+	storage.RateLimitRepository
+}
+
+// AccountOptions 自助账户设置
+type AccountOptions struct {
+	Domain string // 验证邮件的发件域名（no-reply@Domain）
+}
+
+// AccountService 用户自助管理账户：修改用户名和密码、验证邮箱、注销账户
+type AccountService struct {
+	store     domain.Store
+	auth      *auth.Service
+	codes     AccountCodeStore
+	sessions  *auth.SessionService // 修改密码、删除账户时撤销登录会话（可选）
+	mailboxes *MailboxService      // 删除账户时逐个清理邮箱附属资源（可选）
+	closer    UserSessionCloser    // 删除账户时断开实时连接（可选）
+	sender    MailSender           // 发送验证邮件（可选，未配置时不能验证邮箱）
+	options   AccountOptions
+	now       func() time.Time
+}
+
+// NewAccountService 创建账户服务
+func NewAccountService(store domain.Store, authService *auth.Service, codes AccountCodeStore, options AccountOptions) *AccountService {
+	return &AccountService{
+		store:   store,
+		auth:    authService,
+		codes:   codes,
+		options: options,
+		now:     time.Now,
+	}
+}
+
+// SetSessions 设置登录会话服务
+func (s *AccountService) SetSessions(sessions *auth.SessionService) {
+	s.sessions = sessions
+}
+
+// SetMailboxService 设置邮箱服务
+func (s *AccountService) SetMailboxService(mailboxes *MailboxService) {
+	s.mailboxes = mailboxes
+}
+
+// SetSessionCloser 设置实时连接关闭器
+func (s *AccountService) SetSessionCloser(closer UserSessionCloser) {
+	s.closer = closer
+}
+
+// SetMailSender 设置验证邮件的发信中继
+func (s *AccountService) SetMailSender(sender MailSender) {
+	s.sender = sender
+}
+
+// Get 获取当前用户
+func (s *AccountService) Get(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, auth.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// ChangeUsername 修改用户名
+func (s *AccountService) ChangeUsername(ctx context.Context, userID, username string) (*domain.User, error) {
+	return s.auth.ChangeUsername(ctx, userID, username)
+}
+
+// ChangePassword 校验当前密码后修改密码，并注销除 keepSessionID 以外的全部会话
+func (s *AccountService) ChangePassword(ctx context.Context, userID, currentPassword, newPassword, keepSessionID, ip string) error {
+	if err := s.auth.ChangePassword(ctx, userID, currentPassword, newPassword, ip); err != nil {
+		return err
+	}
+	if s.sessions != nil {
+		if err := s.sessions.RevokeAll(ctx, userID, keepSessionID); err != nil {
+			return fmt.Errorf("revoke sessions: %w", err)
+		}
+	}
+	return nil
+}
+
+// SendEmailVerification 向账户邮箱发送验证码，重新发送时旧验证码失效
+func (s *AccountService) SendEmailVerification(ctx context.Context, userID string) error {
+	if s.sender == nil {
+		return ErrEmailVerificationUnavailable
+	}
+	user, err := s.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsEmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	sent, err := s.codes.IncrementRateLimit(ctx, emailCodeSentKey(userID), EmailCodeResendInterval)
+	if err != nil {
+		return err
+	}
+	if sent > 1 {
+		return ErrEmailCodeCooldown
+	}
+
+	code, err := generateEmailCode()
+	if err != nil {
+		return err
+	}
+	if err := s.codes.CacheSession(ctx, emailCodeKey(userID), hashForwardCode(code), EmailCodeTTL); err != nil {
+		return err
+	}
+	if err := s.codes.ResetRateLimit(ctx, emailCodeAttemptsKey(userID)); err != nil {
+		return err
+	}
+
+	from := "no-reply@" + s.options.Domain
+	raw := mailfmt.Compose(mailfmt.Outgoing{
+		From:    from,
+		To:      []string{user.Email},
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf("Use this code to verify the email address of your account:\n\n%s\n\n"+
+			"The code expires in %s. If you did not request this, ignore this message.\n", code, EmailCodeTTL),
+		MessageID: uuid.New().String() + "@" + s.options.Domain,
+		Date:      s.now(),
+	})
+	if err := s.sender.Send(ctx, from, []string{user.Email}, raw); err != nil {
+		_ = s.codes.DeleteCachedSession(ctx, emailCodeKey(userID))
+		_ = s.codes.ResetRateLimit(ctx, emailCodeSentKey(userID))
+		return fmt.Errorf("%w: %v", ErrSendRelayFailed, err)
+	}
+	return nil
+}
+
+// VerifyEmail 提交验证码完成邮箱验证
+func (s *AccountService) VerifyEmail(ctx context.Context, userID, code string) (*domain.User, error) {
+	user, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsEmailVerified {
+		return nil, ErrEmailAlreadyVerified
+	}
+	hash, err := s.codes.GetCachedSession(ctx, emailCodeKey(userID))
+	if err != nil || hash == "" {
+		return nil, ErrEmailCodeExpired
+	}
+	attempts, err := s.codes.IncrementRateLimit(ctx, emailCodeAttemptsKey(userID), EmailCodeTTL)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > MaxEmailVerifyAttempts {
+		_ = s.codes.DeleteCachedSession(ctx, emailCodeKey(userID))
+		return nil, ErrEmailCodeExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(hashForwardCode(strings.TrimSpace(code))), []byte(hash)) != 1 {
+		return nil, ErrEmailCodeInvalid
+	}
+
+	user.IsEmailVerified = true
+	if err := s.store.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	_ = s.codes.DeleteCachedSession(ctx, emailCodeKey(userID))
+	_ = s.codes.ResetRateLimit(ctx, emailCodeAttemptsKey(userID))
+	return user, nil
+}
+
+// DeleteAccount 注销账户并删除其个人邮箱、域名和 API Key
+//
+// 有密码的账户须提供当前密码；仅通过第三方登录、没有密码的账户须提供账户邮箱作为确认。
+// 超级管理员和组织所有者不能自助注销。
+func (s *AccountService) DeleteAccount(ctx context.Context, userID, password, confirm, ip string) error {
+	user, err := s.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Role == domain.RoleSuper {
+		return ErrAccountIsSuper
+	}
+	if user.PasswordHash != "" {
+		if _, err := s.auth.VerifyPassword(ctx, userID, password, ip); err != nil {
+			return err
+		}
+	} else if !strings.EqualFold(strings.TrimSpace(confirm), user.Email) {
+		return ErrAccountConfirmMismatch
+	}
+
+	memberships, err := s.store.ListOrgMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, member := range memberships {
+		if member.Role == domain.OrgRoleOwner {
+			return ErrAccountOwnsOrganization
+		}
+	}
+
+	if err := deleteUserData(ctx, s.store, s.mailboxes, userID); err != nil {
+		return err
+	}
+	if s.sessions != nil {
+		if err := s.sessions.RevokeAll(ctx, userID, ""); err != nil {
+			return fmt.Errorf("revoke sessions: %w", err)
+		}
+	}
+	if s.closer != nil {
+		s.closer.CloseByUserID(userID, "account deleted")
+	}
+	return nil
+}
+
+// deleteUserData 删除用户及其个人数据（组织名下的域名归组织所有，保留）
+func deleteUserData(ctx context.Context, store domain.Store, mailboxes *MailboxService, userID string) error {
+	// 逐个走完整删除流程，剩余的邮箱由存储层兜底删除
+	if mailboxes != nil {
+		if err := mailboxes.DeleteByUserID(ctx, userID); err != nil {
+			return err
+		}
+	}
+	if err := store.DeleteMailboxesByUserID(ctx, userID); err != nil {
+		return err
+	}
+
+	domains, err := store.ListUserDomainsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, userDomain := range domains {
+		if domain.InOrg(userDomain.OrgID) {
+			continue
+		}
+		if err := store.DeleteUserDomain(ctx, userDomain.ID); err != nil {
+			return err
+		}
+	}
+
+	keys, err := store.ListAPIKeysByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := store.DeleteAPIKey(ctx, key.ID); err != nil {
+			return err
+		}
+	}
+
+	memberships, err := store.ListOrgMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, member := range memberships {
+		if err := store.DeleteOrgMember(ctx, member.OrgID, userID); err != nil {
+			return err
+		}
+	}
+
+	return store.DeleteUser(ctx, userID)
+}
+
+func emailCodeKey(userID string) string         { return "email-verify:" + userID }
+func emailCodeAttemptsKey(userID string) string { return "email-verify-attempts:" + userID }
+func emailCodeSentKey(userID string) string     { return "email-verify-sent:" + userID }
+
+// generateEmailCode 生成 6 位数字验证码
+func generateEmailCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
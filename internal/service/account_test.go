@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/auth"
+	"tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+var emailCodePattern = regexp.MustCompile(`account:\s+(\d{6})\s`)
+
+func TestAccountService(t *testing.T) {
+	ctx := context.Background()
+	client := auth.SessionClient{IP: "10.0.0.1", UserAgent: "test-agent"}
+
+	type fixture struct {
+		store    *memory.Store
+		accounts *AccountService
+		sessions *auth.SessionService
+		sender   *fakeMailSender
+		user     *domain.User
+	}
+	setup := func(t *testing.T) *fixture {
+		t.Helper()
+		store := memory.NewStore(time.Hour)
+		authService := auth.NewService(store)
+		user, err := authService.Register(ctx, auth.RegisterInput{Email: "alice@example.com", Password: "password123", Username: "alice"})
+		require.NoError(t, err)
+		tokens := jwt.NewManager("test-secret-0123456789abcdefghijklmnop", "test", 15*time.Minute, 24*time.Hour)
+		sessions := auth.NewSessionService(store, store, tokens)
+		sender := &fakeMailSender{}
+		accounts := NewAccountService(store, authService, store, AccountOptions{Domain: "mx.temp.example"})
+		accounts.SetSessions(sessions)
+		accounts.SetMailSender(sender)
+		return &fixture{store: store, accounts: accounts, sessions: sessions, sender: sender, user: user}
+	}
+	lastCode := func(t *testing.T, sender *fakeMailSender) string {
+		t.Helper()
+		require.NotEmpty(t, sender.sent)
+		match := emailCodePattern.FindStringSubmatch(sender.sent[len(sender.sent)-1].message)
+		require.NotNil(t, match)
+		return match[1]
+	}
+
+	t.Run("修改用户名，已被占用时拒绝", func(t *testing.T) {
+		f := setup(t)
+		_, err := auth.NewService(f.store).Register(ctx, auth.RegisterInput{Email: "bob@example.com", Password: "password123", Username: "bob"})
+		require.NoError(t, err)
+
+		_, err = f.accounts.ChangeUsername(ctx, f.user.ID, "BOB")
+		assert.ErrorIs(t, err, auth.ErrUsernameExists)
+		_, err = f.accounts.ChangeUsername(ctx, f.user.ID, "a@b")
+		assert.ErrorIs(t, err, auth.ErrInvalidUsername)
+
+		user, err := f.accounts.ChangeUsername(ctx, f.user.ID, "alice.w")
+		require.NoError(t, err)
+		assert.Equal(t, "alice.w", user.Username)
+		_, err = f.store.GetUserByUsername(ctx, "alice")
+		assert.Error(t, err, "旧用户名释放")
+		found, err := f.store.GetUserByUsername(ctx, "Alice.W")
+		require.NoError(t, err)
+		assert.Equal(t, f.user.ID, found.ID)
+	})
+
+	t.Run("修改密码需要当前密码，并注销其他会话", func(t *testing.T) {
+		f := setup(t)
+		current, err := f.sessions.Start(ctx, f.user, client)
+		require.NoError(t, err)
+		other, err := f.sessions.Start(ctx, f.user, client)
+		require.NoError(t, err)
+
+		err = f.accounts.ChangePassword(ctx, f.user.ID, "wrong-password", "newpassword456", current.SessionID, "10.0.0.1")
+		assert.ErrorIs(t, err, auth.ErrIncorrectPassword)
+
+		require.NoError(t, f.accounts.ChangePassword(ctx, f.user.ID, "password123", "newpassword456", current.SessionID, "10.0.0.1"))
+		_, err = auth.NewService(f.store).Login(ctx, auth.LoginInput{Identifier: "alice@example.com", Password: "newpassword456"})
+		require.NoError(t, err)
+
+		list, err := f.sessions.List(ctx, f.user.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, current.SessionID, list[0].ID)
+		revoked, err := f.store.IsBlacklisted(ctx, other.AccessID)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("用邮件中的验证码验证邮箱", func(t *testing.T) {
+		f := setup(t)
+		require.NoError(t, f.accounts.SendEmailVerification(ctx, f.user.ID))
+		assert.ErrorIs(t, f.accounts.SendEmailVerification(ctx, f.user.ID), ErrEmailCodeCooldown)
+		require.Len(t, f.sender.sent, 1)
+		assert.Equal(t, []string{"alice@example.com"}, f.sender.sent[0].to)
+		code := lastCode(t, f.sender)
+
+		_, err := f.accounts.VerifyEmail(ctx, f.user.ID, "000000x")
+		assert.ErrorIs(t, err, ErrEmailCodeInvalid)
+		user, err := f.accounts.VerifyEmail(ctx, f.user.ID, code)
+		require.NoError(t, err)
+		assert.True(t, user.IsEmailVerified)
+
+		_, err = f.accounts.VerifyEmail(ctx, f.user.ID, code)
+		assert.ErrorIs(t, err, ErrEmailAlreadyVerified)
+		assert.ErrorIs(t, f.accounts.SendEmailVerification(ctx, f.user.ID), ErrEmailAlreadyVerified)
+	})
+
+	t.Run("验证码尝试次数用尽后失效", func(t *testing.T) {
+		f := setup(t)
+		require.NoError(t, f.accounts.SendEmailVerification(ctx, f.user.ID))
+		code := lastCode(t, f.sender)
+		for i := 0; i < MaxEmailVerifyAttempts; i++ {
+			_, err := f.accounts.VerifyEmail(ctx, f.user.ID, "wrong")
+			require.ErrorIs(t, err, ErrEmailCodeInvalid)
+		}
+		_, err := f.accounts.VerifyEmail(ctx, f.user.ID, code)
+		assert.ErrorIs(t, err, ErrEmailCodeExpired)
+	})
+
+	t.Run("未配置发信中继时不能验证邮箱，发送失败可立即重试", func(t *testing.T) {
+		f := setup(t)
+		f.accounts.SetMailSender(nil)
+		assert.ErrorIs(t, f.accounts.SendEmailVerification(ctx, f.user.ID), ErrEmailVerificationUnavailable)
+
+		f.accounts.SetMailSender(f.sender)
+		f.sender.err = errors.New("relay down")
+		assert.ErrorIs(t, f.accounts.SendEmailVerification(ctx, f.user.ID), ErrSendRelayFailed)
+		f.sender.err = nil
+		assert.NoError(t, f.accounts.SendEmailVerification(ctx, f.user.ID))
+	})
+
+	t.Run("注销账户删除邮箱、个人域名和会话", func(t *testing.T) {
+		f := setup(t)
+		userID := f.user.ID
+		mailbox := &domain.Mailbox{ID: "mb-1", Address: "a@temp.example", LocalPart: "a", Domain: "temp.example", UserID: &userID, CreatedAt: time.Now()}
+		require.NoError(t, f.store.SaveMailbox(ctx, mailbox))
+		require.NoError(t, f.store.SaveUserDomain(ctx, &domain.UserDomain{ID: "d-1", UserID: userID, Domain: "alice.example"}))
+		pair, err := f.sessions.Start(ctx, f.user, client)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, f.accounts.DeleteAccount(ctx, userID, "wrong-password", "", "10.0.0.1"), auth.ErrIncorrectPassword)
+		require.NoError(t, f.accounts.DeleteAccount(ctx, userID, "password123", "", "10.0.0.1"))
+
+		_, err = f.store.GetUserByID(ctx, userID)
+		assert.Error(t, err)
+		_, err = f.store.GetUserByUsername(ctx, "alice")
+		assert.Error(t, err, "用户名可重新注册")
+		_, err = f.store.GetMailbox(ctx, "mb-1")
+		assert.Error(t, err)
+		_, err = f.store.GetUserDomain(ctx, "d-1")
+		assert.Error(t, err)
+		revoked, err := f.store.IsBlacklisted(ctx, pair.AccessID)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("没有密码的账户用邮箱确认注销", func(t *testing.T) {
+		f := setup(t)
+		f.user.PasswordHash = ""
+		require.NoError(t, f.store.UpdateUser(ctx, f.user))
+
+		assert.ErrorIs(t, f.accounts.DeleteAccount(ctx, f.user.ID, "", "bob@example.com", ""), ErrAccountConfirmMismatch)
+		require.NoError(t, f.accounts.DeleteAccount(ctx, f.user.ID, "", "Alice@Example.com", ""))
+	})
+
+	t.Run("组织所有者和超级管理员不能注销", func(t *testing.T) {
+		f := setup(t)
+		require.NoError(t, f.store.CreateOrganization(ctx, &domain.Organization{ID: "org-1", Name: "Acme", OwnerID: f.user.ID}))
+		require.NoError(t, f.store.SaveOrgMember(ctx, &domain.OrgMember{OrgID: "org-1", UserID: f.user.ID, Role: domain.OrgRoleOwner}))
+		assert.ErrorIs(t, f.accounts.DeleteAccount(ctx, f.user.ID, "password123", "", ""), ErrAccountOwnsOrganization)
+
+		f.user.Role = domain.RoleSuper
+		require.NoError(t, f.store.UpdateUser(ctx, f.user))
+		assert.ErrorIs(t, f.accounts.DeleteAccount(ctx, f.user.ID, "password123", "", ""), ErrAccountIsSuper)
+	})
+}
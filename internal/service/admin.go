@@ -1,7 +1,9 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -24,8 +26,15 @@ var (
 
 // AdminService 管理服务
 type AdminService struct {
-	store  domain.Store
-	config *domain.Config
+	store     domain.Store
+	config    *domain.Config
+	mailboxes *MailboxService   // 邮箱删除统一走 MailboxService（可选）
+	sessions  UserSessionCloser // 停用用户时断开实时连接（可选）
+}
+
+// UserSessionCloser 断开用户的实时连接（由 WebSocket Hub 实现）
+type UserSessionCloser interface {
+	CloseByUserID(userID, reason string)
 }
 
 // NewAdminService 创建管理服务
@@ -36,6 +45,112 @@ func NewAdminService(store domain.Store, config *domain.Config) *AdminService {
 	}
 }
 
+// SetMailboxService 设置邮箱服务，删除用户和强制删除邮箱时清理全部附属资源
+func (s *AdminService) SetMailboxService(mailboxes *MailboxService) {
+	s.mailboxes = mailboxes
+}
+
+// SetSessionCloser 设置用户连接断开通知（避免依赖 websocket 包）
+func (s *AdminService) SetSessionCloser(closer UserSessionCloser) {
+	s.sessions = closer
+}
+
+// closeSessions 断开用户的实时连接
+func (s *AdminService) closeSessions(userID, reason string) {
+	if s.sessions != nil {
+		s.sessions.CloseByUserID(userID, reason)
+	}
+}
+
+// SetMailboxPublic 设置邮箱是否为公开收件箱（取消公开时撤销匿名订阅）
+func (s *AdminService) SetMailboxPublic(ctx context.Context, mailboxID string, public bool) (*domain.Mailbox, error) {
+	if s.mailboxes != nil {
+		return s.mailboxes.SetPublic(ctx, mailboxID, public)
+	}
+	mailbox, err := s.store.GetMailbox(ctx, mailboxID)
+	if err != nil {
+		return nil, err
+	}
+	mailbox.IsPublic = public
+	if err := s.store.SaveMailbox(ctx, mailbox); err != nil {
+		return nil, err
+	}
+	return mailbox, nil
+}
+
+// ForceDeleteMailbox 强制删除邮箱（无需邮箱 Token）
+func (s *AdminService) ForceDeleteMailbox(ctx context.Context, mailboxID string) error {
+	if s.mailboxes != nil {
+		return s.mailboxes.Delete(ctx, mailboxID)
+	}
+	return s.store.DeleteMailbox(ctx, mailboxID)
+}
+
+// ListMailboxesInput 管理员列出邮箱的输入参数
+type ListMailboxesInput struct {
+	Page     int
+	PageSize int
+	IdleOnly bool // 只列出闲置邮箱
+}
+
+// ListMailboxesOutput 管理员列出邮箱的输出结果
+type ListMailboxesOutput struct {
+	Mailboxes  []domain.Mailbox `json:"mailboxes"`
+	Total      int              `json:"total"`
+	Page       int              `json:"page"`
+	PageSize   int              `json:"pageSize"`
+	TotalPages int              `json:"totalPages"`
+}
+
+// ListMailboxes 列出所有邮箱，按最近访问时间从早到晚排序（需要管理员权限）
+func (s *AdminService) ListMailboxes(ctx context.Context, input ListMailboxesInput) (*ListMailboxesOutput, error) {
+	if input.Page <= 0 {
+		input.Page = 1
+	}
+	if input.PageSize <= 0 {
+		input.PageSize = 20
+	}
+	if input.PageSize > 100 {
+		input.PageSize = 100
+	}
+
+	all := s.store.ListMailboxes(ctx)
+	mailboxes := make([]domain.Mailbox, 0, len(all))
+	for _, mb := range all {
+		if input.IdleOnly && mb.IdleSince == nil {
+			continue
+		}
+		mailboxes = append(mailboxes, mb)
+	}
+	lastActive := func(mb domain.Mailbox) time.Time {
+		if mb.LastAccessedAt != nil {
+			return *mb.LastAccessedAt
+		}
+		return mb.CreatedAt
+	}
+	sort.SliceStable(mailboxes, func(i, j int) bool {
+		return lastActive(mailboxes[i]).Before(lastActive(mailboxes[j]))
+	})
+
+	total := len(mailboxes)
+	start := (input.Page - 1) * input.PageSize
+	if start > total {
+		start = total
+	}
+	end := start + input.PageSize
+	if end > total {
+		end = total
+	}
+
+	return &ListMailboxesOutput{
+		Mailboxes:  mailboxes[start:end],
+		Total:      total,
+		Page:       input.Page,
+		PageSize:   input.PageSize,
+		TotalPages: (total + input.PageSize - 1) / input.PageSize,
+	}, nil
+}
+
 // ListUsersInput 列出用户的输入参数
 type ListUsersInput struct {
 	Page     int
@@ -56,7 +171,7 @@ type ListUsersOutput struct {
 }
 
 // ListUsers 列出所有用户（需要管理员权限）
-func (s *AdminService) ListUsers(input ListUsersInput) (*ListUsersOutput, error) {
+func (s *AdminService) ListUsers(ctx context.Context, input ListUsersInput) (*ListUsersOutput, error) {
 	// 设置默认分页
 	if input.Page <= 0 {
 		input.Page = 1
@@ -69,7 +184,7 @@ func (s *AdminService) ListUsers(input ListUsersInput) (*ListUsersOutput, error)
 	}
 
 	// 获取用户列表
-	users, total, err := s.store.ListUsers(input.Page, input.PageSize, input.Search, input.Role, input.Tier, input.IsActive)
+	users, total, err := s.store.ListUsers(ctx, input.Page, input.PageSize, input.Search, input.Role, input.Tier, input.IsActive)
 	if err != nil {
 		return nil, err
 	}
@@ -86,8 +201,8 @@ func (s *AdminService) ListUsers(input ListUsersInput) (*ListUsersOutput, error)
 }
 
 // GetUser 获取用户详情（需要管理员权限）
-func (s *AdminService) GetUser(userID string) (*domain.User, error) {
-	user, err := s.store.GetUserByID(userID)
+func (s *AdminService) GetUser(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, ErrAdminUserNotFound
 	}
@@ -105,20 +220,20 @@ type UpdateUserInput struct {
 }
 
 // UpdateUser 更新用户信息（需要管理员权限）
-func (s *AdminService) UpdateUser(input UpdateUserInput) (*domain.User, error) {
+func (s *AdminService) UpdateUser(ctx context.Context, input UpdateUserInput) (*domain.User, error) {
 	// 不能修改自己
 	if input.UserID == input.OperatorID {
 		return nil, ErrCannotModifySelf
 	}
 
 	// 获取目标用户
-	user, err := s.store.GetUserByID(input.UserID)
+	user, err := s.store.GetUserByID(ctx, input.UserID)
 	if err != nil {
 		return nil, ErrAdminUserNotFound
 	}
 
 	// 获取操作者
-	operator, err := s.store.GetUserByID(input.OperatorID)
+	operator, err := s.store.GetUserByID(ctx, input.OperatorID)
 	if err != nil {
 		return nil, ErrUnauthorized
 	}
@@ -141,7 +256,9 @@ func (s *AdminService) UpdateUser(input UpdateUserInput) (*domain.User, error) {
 		user.Tier = *input.Tier
 	}
 
+	deactivated := false
 	if input.IsActive != nil {
+		deactivated = user.IsActive && !*input.IsActive
 		user.IsActive = *input.IsActive
 	}
 
@@ -152,22 +269,27 @@ func (s *AdminService) UpdateUser(input UpdateUserInput) (*domain.User, error) {
 	user.UpdatedAt = time.Now()
 
 	// 保存更新
-	if err := s.store.UpdateUser(user); err != nil {
+	if err := s.store.UpdateUser(ctx, user); err != nil {
 		return nil, err
 	}
 
+	// 停用立即生效：已建立的 WebSocket 连接不等令牌过期
+	if deactivated {
+		s.closeSessions(user.ID, "user deactivated")
+	}
+
 	return user, nil
 }
 
 // DeleteUser 删除用户（需要超级管理员权限）
-func (s *AdminService) DeleteUser(userID, operatorID string) error {
+func (s *AdminService) DeleteUser(ctx context.Context, userID, operatorID string) error {
 	// 不能删除自己
 	if userID == operatorID {
 		return ErrCannotModifySelf
 	}
 
 	// 获取目标用户
-	user, err := s.store.GetUserByID(userID)
+	user, err := s.store.GetUserByID(ctx, userID)
 	if err != nil {
 		return ErrAdminUserNotFound
 	}
@@ -177,18 +299,17 @@ func (s *AdminService) DeleteUser(userID, operatorID string) error {
 		return ErrCannotModifySuper
 	}
 
-	// 删除用户的邮箱
-	if err := s.store.DeleteMailboxesByUserID(userID); err != nil {
+	// 删除用户及其邮箱、个人域名和 API Key
+	if err := deleteUserData(ctx, s.store, s.mailboxes, userID); err != nil {
 		return err
 	}
-
-	// 删除用户
-	return s.store.DeleteUser(userID)
+	s.closeSessions(userID, "user deleted")
+	return nil
 }
 
 // GetStatistics 获取系统统计（需要管理员权限）
-func (s *AdminService) GetStatistics() (*domain.SystemStatistics, error) {
-	stats, err := s.store.GetSystemStatistics()
+func (s *AdminService) GetStatistics(ctx context.Context) (*domain.SystemStatistics, error) {
+	stats, err := s.store.GetSystemStatistics(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -204,7 +325,7 @@ type CreateAdminUserInput struct {
 }
 
 // CreateAdminUser 创建管理员用户（仅用于开发测试）
-func (s *AdminService) CreateAdminUser(input CreateAdminUserInput) (*domain.User, error) {
+func (s *AdminService) CreateAdminUser(ctx context.Context, input CreateAdminUserInput) (*domain.User, error) {
 	// 验证邮箱格式
 	if !auth.ValidateEmail(input.Email) {
 		return nil, auth.ErrInvalidEmail
@@ -216,7 +337,7 @@ func (s *AdminService) CreateAdminUser(input CreateAdminUserInput) (*domain.User
 	}
 
 	// 检查邮箱是否已存在
-	if _, err := s.store.GetUserByEmail(input.Email); err == nil {
+	if _, err := s.store.GetUserByEmail(ctx, input.Email); err == nil {
 		return nil, auth.ErrEmailExists
 	}
 
@@ -241,7 +362,7 @@ func (s *AdminService) CreateAdminUser(input CreateAdminUserInput) (*domain.User
 	user.PasswordHash = hashedPassword
 
 	// 保存用户
-	if err := s.store.CreateUser(user); err != nil {
+	if err := s.store.CreateUser(ctx, user); err != nil {
 		return nil, err
 	}
 
@@ -277,7 +398,7 @@ type DomainInfo struct {
 }
 
 // ListDomains 列出所有域名（需要管理员权限）
-func (s *AdminService) ListDomains(input ListDomainsInput) (*ListDomainsOutput, error) {
+func (s *AdminService) ListDomains(ctx context.Context, input ListDomainsInput) (*ListDomainsOutput, error) {
 	// 设置默认分页
 	if input.Page <= 0 {
 		input.Page = 1
@@ -292,7 +413,7 @@ func (s *AdminService) ListDomains(input ListDomainsInput) (*ListDomainsOutput,
 
 	for i, domain := range allowedDomains {
 		// 获取域名的统计信息
-		mailboxCount, messageCount, err := s.store.GetDomainStatistics(domain)
+		mailboxCount, messageCount, err := s.store.GetDomainStatistics(ctx, domain)
 		if err != nil {
 			continue
 		}
@@ -376,8 +497,8 @@ func (s *AdminService) RemoveDomain(domain string) error {
 }
 
 // GetUserQuota 获取用户配额（需要管理员权限）
-func (s *AdminService) GetUserQuota(userID string) (*domain.Quota, error) {
-	user, err := s.store.GetUserByID(userID)
+func (s *AdminService) GetUserQuota(ctx context.Context, userID string) (*domain.Quota, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, ErrAdminUserNotFound
 	}
@@ -388,9 +509,9 @@ func (s *AdminService) GetUserQuota(userID string) (*domain.Quota, error) {
 }
 
 // UpdateUserQuota 更新用户配额（需要管理员权限）
-func (s *AdminService) UpdateUserQuota(userID string, quota domain.Quota) error {
+func (s *AdminService) UpdateUserQuota(ctx context.Context, userID string, quota domain.Quota) error {
 	// 验证用户存在
-	_, err := s.store.GetUserByID(userID)
+	_, err := s.store.GetUserByID(ctx, userID)
 	if err != nil {
 		return ErrAdminUserNotFound
 	}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -12,11 +13,17 @@ import (
 	"tempmail/backend/internal/storage"
 )
 
+// AllowedDomainSource 提供当前允许的系统域名（MailboxService 实现，随系统配置变化）
+type AllowedDomainSource interface {
+	AllowedDomains() []string
+}
+
 // AliasService 封装邮箱别名处理逻辑。
 type AliasService struct {
 	aliasRepo   storage.AliasRepository
 	mailboxRepo storage.MailboxRepository
 	cfg         *config.Config
+	domains     AllowedDomainSource // 允许的域名（可选，未设置时使用启动配置）
 }
 
 // NewAliasService 创建别名业务服务。
@@ -28,6 +35,19 @@ func NewAliasService(aliasRepo storage.AliasRepository, mailboxRepo storage.Mail
 	}
 }
 
+// SetAllowedDomainSource 设置允许域名的来源，使系统配置的修改即时生效
+func (s *AliasService) SetAllowedDomainSource(source AllowedDomainSource) {
+	s.domains = source
+}
+
+// allowedDomains 当前允许的系统域名
+func (s *AliasService) allowedDomains() []string {
+	if s.domains != nil {
+		return s.domains.AllowedDomains()
+	}
+	return s.cfg.Mailbox.AllowedDomains
+}
+
 // CreateAliasInput 定义创建别名的输入。
 type CreateAliasInput struct {
 	MailboxID string
@@ -35,9 +55,9 @@ type CreateAliasInput struct {
 }
 
 // Create 创建一个新的邮箱别名。
-func (s *AliasService) Create(input CreateAliasInput) (*domain.MailboxAlias, error) {
+func (s *AliasService) Create(ctx context.Context, input CreateAliasInput) (*domain.MailboxAlias, error) {
 	// 验证邮箱是否存在
-	mailbox, err := s.mailboxRepo.GetMailbox(input.MailboxID)
+	mailbox, err := s.mailboxRepo.GetMailbox(ctx, input.MailboxID)
 	if err != nil {
 		return nil, fmt.Errorf("mailbox not found: %w", err)
 	}
@@ -59,7 +79,7 @@ func (s *AliasService) Create(input CreateAliasInput) (*domain.MailboxAlias, err
 
 	// 检查域名是否允许
 	allowed := false
-	for _, allowedDomain := range s.cfg.Mailbox.AllowedDomains {
+	for _, allowedDomain := range s.allowedDomains() {
 		if domainName == allowedDomain {
 			allowed = true
 			break
@@ -83,7 +103,7 @@ func (s *AliasService) Create(input CreateAliasInput) (*domain.MailboxAlias, err
 		IsActive:  true,
 	}
 
-	if err := s.aliasRepo.SaveAlias(alias); err != nil {
+	if err := s.aliasRepo.SaveAlias(ctx, alias); err != nil {
 		return nil, fmt.Errorf("failed to save alias: %w", err)
 	}
 
@@ -91,30 +111,30 @@ func (s *AliasService) Create(input CreateAliasInput) (*domain.MailboxAlias, err
 }
 
 // List 列出指定邮箱的所有别名。
-func (s *AliasService) List(mailboxID string) ([]*domain.MailboxAlias, error) {
+func (s *AliasService) List(ctx context.Context, mailboxID string) ([]*domain.MailboxAlias, error) {
 	// 验证邮箱是否存在
-	if _, err := s.mailboxRepo.GetMailbox(mailboxID); err != nil {
+	if _, err := s.mailboxRepo.GetMailbox(ctx, mailboxID); err != nil {
 		return nil, fmt.Errorf("mailbox not found: %w", err)
 	}
 
-	return s.aliasRepo.ListAliasesByMailboxID(mailboxID)
+	return s.aliasRepo.ListAliasesByMailboxID(ctx, mailboxID)
 }
 
 // Get 获取别名详情。
-func (s *AliasService) Get(aliasID string) (*domain.MailboxAlias, error) {
-	return s.aliasRepo.GetAlias(aliasID)
+func (s *AliasService) Get(ctx context.Context, aliasID string) (*domain.MailboxAlias, error) {
+	return s.aliasRepo.GetAlias(ctx, aliasID)
 }
 
 // GetByAddress 根据地址获取别名。
-func (s *AliasService) GetByAddress(address string) (*domain.MailboxAlias, error) {
+func (s *AliasService) GetByAddress(ctx context.Context, address string) (*domain.MailboxAlias, error) {
 	address = strings.ToLower(strings.TrimSpace(address))
-	return s.aliasRepo.GetAliasByAddress(address)
+	return s.aliasRepo.GetAliasByAddress(ctx, address)
 }
 
 // Delete 删除别名。
-func (s *AliasService) Delete(mailboxID, aliasID string) error {
+func (s *AliasService) Delete(ctx context.Context, mailboxID, aliasID string) error {
 	// 获取别名
-	alias, err := s.aliasRepo.GetAlias(aliasID)
+	alias, err := s.aliasRepo.GetAlias(ctx, aliasID)
 	if err != nil {
 		return fmt.Errorf("alias not found: %w", err)
 	}
@@ -124,13 +144,13 @@ func (s *AliasService) Delete(mailboxID, aliasID string) error {
 		return fmt.Errorf("alias does not belong to this mailbox")
 	}
 
-	return s.aliasRepo.DeleteAlias(aliasID)
+	return s.aliasRepo.DeleteAlias(ctx, aliasID)
 }
 
 // Toggle 切换别名的激活状态。
-func (s *AliasService) Toggle(mailboxID, aliasID string, isActive bool) error {
+func (s *AliasService) Toggle(ctx context.Context, mailboxID, aliasID string, isActive bool) error {
 	// 获取别名
-	alias, err := s.aliasRepo.GetAlias(aliasID)
+	alias, err := s.aliasRepo.GetAlias(ctx, aliasID)
 	if err != nil {
 		return fmt.Errorf("alias not found: %w", err)
 	}
@@ -142,5 +162,5 @@ func (s *AliasService) Toggle(mailboxID, aliasID string, isActive bool) error {
 
 	// 更新状态
 	alias.IsActive = isActive
-	return s.aliasRepo.SaveAlias(alias)
+	return s.aliasRepo.SaveAlias(ctx, alias)
 }
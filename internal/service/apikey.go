@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
@@ -44,9 +45,9 @@ type CreateAPIKeyInput struct {
 // 返回值:
 //   - *domain.APIKey: 创建的API Key
 //   - error: 错误信息
-func (s *APIKeyService) CreateAPIKey(input CreateAPIKeyInput) (*domain.APIKey, error) {
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (*domain.APIKey, error) {
 	// 验证用户是否存在
-	_, err := s.store.GetUserByID(input.UserID)
+	_, err := s.store.GetUserByID(ctx, input.UserID)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
@@ -81,7 +82,7 @@ func (s *APIKeyService) CreateAPIKey(input CreateAPIKeyInput) (*domain.APIKey, e
 		ExpiresAt: expiresAt,
 	}
 
-	if err := s.store.SaveAPIKey(apiKey); err != nil {
+	if err := s.store.SaveAPIKey(ctx, apiKey); err != nil {
 		return nil, err
 	}
 
@@ -96,8 +97,8 @@ func (s *APIKeyService) CreateAPIKey(input CreateAPIKeyInput) (*domain.APIKey, e
 // 返回值:
 //   - []*domain.APIKey: API Key列表
 //   - error: 错误信息
-func (s *APIKeyService) ListAPIKeys(userID string) ([]*domain.APIKey, error) {
-	return s.store.ListAPIKeysByUserID(userID)
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID string) ([]*domain.APIKey, error) {
+	return s.store.ListAPIKeysByUserID(ctx, userID)
 }
 
 // GetAPIKey 获取API Key详情
@@ -108,8 +109,8 @@ func (s *APIKeyService) ListAPIKeys(userID string) ([]*domain.APIKey, error) {
 // 返回值:
 //   - *domain.APIKey: API Key详情
 //   - error: 错误信息
-func (s *APIKeyService) GetAPIKey(id string) (*domain.APIKey, error) {
-	apiKey, err := s.store.GetAPIKey(id)
+func (s *APIKeyService) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	apiKey, err := s.store.GetAPIKey(ctx, id)
 	if err != nil {
 		return nil, ErrAPIKeyNotFound
 	}
@@ -124,9 +125,9 @@ func (s *APIKeyService) GetAPIKey(id string) (*domain.APIKey, error) {
 //
 // 返回值:
 //   - error: 错误信息
-func (s *APIKeyService) DeleteAPIKey(userID, id string) error {
+func (s *APIKeyService) DeleteAPIKey(ctx context.Context, userID, id string) error {
 	// 获取API Key
-	apiKey, err := s.store.GetAPIKey(id)
+	apiKey, err := s.store.GetAPIKey(ctx, id)
 	if err != nil {
 		return ErrAPIKeyNotFound
 	}
@@ -136,7 +137,7 @@ func (s *APIKeyService) DeleteAPIKey(userID, id string) error {
 		return errors.New("permission denied")
 	}
 
-	return s.store.DeleteAPIKey(id)
+	return s.store.DeleteAPIKey(ctx, id)
 }
 
 // ValidateAPIKey 验证API Key并返回关联的用户
@@ -147,9 +148,9 @@ func (s *APIKeyService) DeleteAPIKey(userID, id string) error {
 // 返回值:
 //   - *domain.User: 关联的用户
 //   - error: 错误信息
-func (s *APIKeyService) ValidateAPIKey(key string) (*domain.User, error) {
+func (s *APIKeyService) ValidateAPIKey(ctx context.Context, key string) (*domain.User, error) {
 	// 获取API Key
-	apiKey, err := s.store.GetAPIKeyByKey(key)
+	apiKey, err := s.store.GetAPIKeyByKey(ctx, key)
 	if err != nil {
 		return nil, ErrAPIKeyInvalid
 	}
@@ -165,10 +166,10 @@ func (s *APIKeyService) ValidateAPIKey(key string) (*domain.User, error) {
 	}
 
 	// 更新最后使用时间
-	_ = s.store.UpdateAPIKeyLastUsed(apiKey.ID)
+	_ = s.store.UpdateAPIKeyLastUsed(ctx, apiKey.ID)
 
 	// 获取用户信息
-	user, err := s.store.GetUserByID(apiKey.UserID)
+	user, err := s.store.GetUserByID(ctx, apiKey.UserID)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
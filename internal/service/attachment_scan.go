@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+)
+
+// 支持的附件扫描服务提供方
+const (
+	ScanProviderOff    = "off"
+	ScanProviderClamAV = "clamav"
+)
+
+var (
+	ErrScannerNotConfigured = errors.New("attachment scanner not configured")
+	ErrUnknownScanner       = errors.New("unknown attachment scanner")
+	ErrScannerMissingAddr   = errors.New("attachment scanner address required")
+	ErrScannerUnavailable   = errors.New("attachment scanner unavailable")
+	ErrAttachmentInfected   = errors.New("attachment is infected")
+)
+
+// 扫描结果指标标签
+const (
+	ScanOutcomeClean       = "clean"
+	ScanOutcomeInfected    = "infected"
+	ScanOutcomeUnavailable = "unavailable"
+)
+
+// AttachmentScanner 附件病毒扫描接口
+//
+// Scan 读取附件内容并返回检出的威胁名称，未检出时返回空字符串；实现需在 ctx 取消时尽快返回。
+type AttachmentScanner interface {
+	Name() string
+	Scan(ctx context.Context, content io.Reader) (threat string, err error)
+}
+
+// ScanMetrics 扫描指标记录器（由 monitoring.Metrics 实现）
+type ScanMetrics interface {
+	RecordAttachmentScan(outcome string)
+}
+
+// NewAttachmentScanner 根据配置创建扫描器，未启用时返回 ErrScannerNotConfigured
+func NewAttachmentScanner(cfg config.ScanConfig) (AttachmentScanner, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case "", ScanProviderOff, "none":
+		return nil, ErrScannerNotConfigured
+	case ScanProviderClamAV:
+		if cfg.Address == "" {
+			return nil, ErrScannerMissingAddr
+		}
+		return NewClamAVScanner(cfg.Address), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownScanner, cfg.Provider)
+	}
+}
+
+// AttachmentScanService 入库前扫描邮件附件
+type AttachmentScanService struct {
+	scanner  AttachmentScanner
+	timeout  time.Duration
+	failOpen bool
+	metrics  ScanMetrics // 可选
+}
+
+// NewAttachmentScanService 使用指定扫描器创建扫描服务
+func NewAttachmentScanService(scanner AttachmentScanner, cfg config.ScanConfig) *AttachmentScanService {
+	s := &AttachmentScanService{
+		scanner:  scanner,
+		timeout:  cfg.Timeout,
+		failOpen: cfg.FailOpen,
+	}
+	if s.timeout <= 0 {
+		s.timeout = 30 * time.Second
+	}
+	return s
+}
+
+// SetMetrics 设置扫描指标记录器
+func (s *AttachmentScanService) SetMetrics(metrics ScanMetrics) {
+	s.metrics = metrics
+}
+
+// Name 扫描服务名称
+func (s *AttachmentScanService) Name() string {
+	return s.scanner.Name()
+}
+
+// Scan 扫描一封邮件的全部附件，检出威胁的附件标记 Infected 和 Threat，返回是否检出
+//
+// 同一封邮件共用一个超时。扫描服务超时或出错时按 fail-open 配置处理：fail-open 视为未检出
+// 照常投递，否则返回 ErrScannerUnavailable（SMTP 层返回 451 让发件方稍后重试）。
+func (s *AttachmentScanService) Scan(ctx context.Context, attachments []*domain.Attachment) (bool, error) {
+	if len(attachments) == 0 {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	infected := false
+	for _, att := range attachments {
+		threat, err := s.scanAttachment(ctx, att)
+		if err != nil {
+			s.record(ScanOutcomeUnavailable)
+			if s.failOpen {
+				return infected, nil
+			}
+			return false, fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+		}
+		if threat != "" {
+			att.Infected = true
+			att.Threat = threat
+			infected = true
+		}
+	}
+
+	if infected {
+		s.record(ScanOutcomeInfected)
+	} else {
+		s.record(ScanOutcomeClean)
+	}
+	return infected, nil
+}
+
+// scanAttachment 扫描单个附件（大附件从暂存 blob 流式读取）
+func (s *AttachmentScanService) scanAttachment(ctx context.Context, att *domain.Attachment) (string, error) {
+	content, err := att.Open()
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+	return s.scanner.Scan(ctx, content)
+}
+
+func (s *AttachmentScanService) record(outcome string) {
+	if s.metrics != nil {
+		s.metrics.RecordAttachmentScan(outcome)
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+var ErrAuditQueryInvalid = errors.New("invalid audit log query")
+
+// 审计记录分页
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 200
+)
+
+// 有专门名称的管理操作（其余写操作以 "方法 路由" 记录）
+const (
+	AuditUserUpdate      = "user.update"
+	AuditUserDelete      = "user.delete"
+	AuditUserQuotaUpdate = "user.quota.update"
+	AuditUserImpersonate = "user.impersonate"
+	AuditDomainDelete    = "domain.delete"
+	AuditConfigUpdate    = "config.update"
+	AuditConfigReset     = "config.reset"
+	AuditMaintenanceSet  = "config.maintenance"
+)
+
+// AuditLogPage 审计记录分页结果
+type AuditLogPage struct {
+	Items  []*domain.AuditLog `json:"items"`
+	Total  int64              `json:"total"`
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+}
+
+// AuditService 管理操作审计记录
+type AuditService struct {
+	store storage.AuditLogRepository
+	now   func() time.Time
+}
+
+// NewAuditService 创建审计服务
+func NewAuditService(store storage.AuditLogRepository) *AuditService {
+	return &AuditService{store: store, now: time.Now}
+}
+
+// Record 写入一条审计记录（补全 ID 和时间）
+func (s *AuditService) Record(ctx context.Context, log *domain.AuditLog) error {
+	if log.ID == "" {
+		log.ID = uuid.NewString()
+	}
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = s.now()
+	}
+	return s.store.CreateAuditLog(ctx, log)
+}
+
+// List 按条件分页查询审计记录（新记录在前）
+func (s *AuditService) List(ctx context.Context, filter domain.AuditLogFilter) (*AuditLogPage, error) {
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, ErrAuditQueryInvalid
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return nil, ErrAuditQueryInvalid
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultAuditPageSize
+	}
+	filter.Limit = min(filter.Limit, MaxAuditPageSize)
+
+	logs, total, err := s.store.ListAuditLogs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if logs == nil {
+		logs = []*domain.AuditLog{}
+	}
+	return &AuditLogPage{Items: logs, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// AuditDiff 比较两个对象的 JSON 字段，返回发生变化的顶层字段（按字段名排序）
+//
+// 只比较会序列化的字段（json:"-" 的密码哈希等不会出现在记录中）。before 为 nil 表示新建，after 为 nil 表示删除；
+// 两者也可以是 AuditSnapshot 的结果。
+func AuditDiff(before, after any) []domain.AuditChange {
+	from, to := AuditSnapshot(before), AuditSnapshot(after)
+	fields := make(map[string]struct{}, len(from)+len(to))
+	for field := range from {
+		fields[field] = struct{}{}
+	}
+	for field := range to {
+		fields[field] = struct{}{}
+	}
+
+	var changes []domain.AuditChange
+	for field := range fields {
+		if !reflect.DeepEqual(from[field], to[field]) {
+			changes = append(changes, domain.AuditChange{Field: field, From: from[field], To: to[field]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// AuditSnapshot 对象序列化后的顶层字段（nil 或无法序列化时为空）
+//
+// 修改前先取快照再与修改后的对象比较，避免存储返回的共享对象被原地修改后比较不出变化。
+func AuditSnapshot(value any) map[string]any {
+	if value == nil || (reflect.ValueOf(value).Kind() == reflect.Pointer && reflect.ValueOf(value).IsNil()) {
+		return nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestAuditService(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	audit := NewAuditService(store)
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("字段变更", func(t *testing.T) {
+		before := &domain.User{ID: "u1", Email: "a@example.com", Tier: domain.TierFree, PasswordHash: "old"}
+		snapshot := AuditSnapshot(before)
+		before.Tier, before.PasswordHash = domain.TierPro, "new"
+
+		changes := AuditDiff(snapshot, before)
+		require.Len(t, changes, 1, "不记录不序列化的密码哈希")
+		assert.Equal(t, domain.AuditChange{Field: "tier", From: "free", To: "pro"}, changes[0])
+
+		assert.Empty(t, AuditDiff(before, before))
+		deleted := AuditDiff(&domain.User{ID: "u1"}, nil)
+		require.NotEmpty(t, deleted)
+		for _, change := range deleted {
+			assert.Nil(t, change.To)
+		}
+	})
+
+	t.Run("按条件分页查询", func(t *testing.T) {
+		for i, action := range []string{AuditUserUpdate, AuditDomainDelete, AuditUserUpdate, AuditConfigUpdate} {
+			audit.now = func() time.Time { return base.Add(time.Duration(i) * time.Minute) }
+			require.NoError(t, audit.Record(t.Context(), &domain.AuditLog{ActorID: "admin-1", Action: action, TargetID: "t1"}))
+		}
+
+		page, err := audit.List(t.Context(), domain.AuditLogFilter{Action: AuditUserUpdate})
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, page.Total)
+		assert.Equal(t, DefaultAuditPageSize, page.Limit)
+		require.Len(t, page.Items, 2)
+		assert.True(t, page.Items[0].CreatedAt.After(page.Items[1].CreatedAt), "新记录在前")
+
+		page, err = audit.List(t.Context(), domain.AuditLogFilter{Since: base.Add(time.Minute), Until: base.Add(3 * time.Minute), Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, page.Total)
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, AuditDomainDelete, page.Items[0].Action)
+
+		page, err = audit.List(t.Context(), domain.AuditLogFilter{Limit: 1000})
+		require.NoError(t, err)
+		assert.Equal(t, MaxAuditPageSize, page.Limit)
+	})
+
+	t.Run("查询条件无效", func(t *testing.T) {
+		_, err := audit.List(t.Context(), domain.AuditLogFilter{Offset: -1})
+		assert.ErrorIs(t, err, ErrAuditQueryInvalid)
+		_, err = audit.List(t.Context(), domain.AuditLogFilter{Since: base, Until: base})
+		assert.ErrorIs(t, err, ErrAuditQueryInvalid)
+	})
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ErrAccessDenied 无权访问资源
+var ErrAccessDenied = errors.New("access denied")
+
+// Action 资源操作类型
+type Action int
+
+const (
+	ActionRead   Action = iota // 查看
+	ActionWrite                // 修改（组织成员即可）
+	ActionManage               // 管理（组织资源需要 owner 角色）
+)
+
+// OrgMemberLookup 查询组织成员关系
+type OrgMemberLookup interface {
+	GetOrgMember(ctx context.Context, orgID, userID string) (*domain.OrgMember, error)
+}
+
+// Authorizer 资源访问授权
+//
+// 个人资源（OrgID 为空）仅所有者可访问；组织资源按实时成员关系判断，
+// 成员被移除后立即失去访问权限。
+type Authorizer struct {
+	members OrgMemberLookup
+}
+
+// NewAuthorizer 创建授权器
+func NewAuthorizer(members OrgMemberLookup) *Authorizer {
+	return &Authorizer{members: members}
+}
+
+// Can 判断用户能否对资源执行操作（ownerID 为资源的个人所有者）
+func (a *Authorizer) Can(ctx context.Context, userID, ownerID string, orgID *string, action Action) bool {
+	if userID == "" {
+		return false
+	}
+	if !domain.InOrg(orgID) {
+		return ownerID == userID
+	}
+	if a == nil || a.members == nil {
+		return false
+	}
+	member, err := a.members.GetOrgMember(ctx, *orgID, userID)
+	if err != nil {
+		return false
+	}
+	if action == ActionManage {
+		return member.Role == domain.OrgRoleOwner
+	}
+	return true
+}
+
+// CanAccessMailbox 判断用户能否访问邮箱（游客邮箱不属于任何用户）
+func (a *Authorizer) CanAccessMailbox(ctx context.Context, userID string, mailbox *domain.Mailbox, action Action) bool {
+	owner := ""
+	if mailbox.UserID != nil {
+		owner = *mailbox.UserID
+	}
+	if owner == "" && !domain.InOrg(mailbox.OrgID) {
+		return false
+	}
+	return a.Can(ctx, userID, owner, mailbox.OrgID, action)
+}
+
+// RequireOrgRole 要求用户在组织中拥有指定角色（member 角色表示任意成员）
+func (a *Authorizer) RequireOrgRole(ctx context.Context, userID, orgID string, role domain.OrgRole) (*domain.OrgMember, error) {
+	if a == nil || a.members == nil || userID == "" || orgID == "" {
+		return nil, ErrNotOrgMember
+	}
+	member, err := a.members.GetOrgMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, ErrNotOrgMember
+	}
+	if role == domain.OrgRoleOwner && member.Role != domain.OrgRoleOwner {
+		return nil, ErrOrgOwnerRequired
+	}
+	return member, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"tempmail/backend/internal/domain"
+)
+
+// MaxCatchAllMailboxes 通配收件自动创建邮箱时域名下的邮箱数上限（超出后不再自动创建，收件人按不存在拒收）
+const MaxCatchAllMailboxes = 1000
+
+var (
+	ErrCatchAllRequiresVerified = errors.New("catch-all requires a verified domain in exclusive or catch-all mode")
+	ErrCatchAllMailbox          = errors.New("catch-all mailbox not found")
+	ErrCatchAllUnavailable      = errors.New("catch-all delivery unavailable")
+)
+
+// CatchAllService 用户域名通配收件
+//
+// 设置保存在域名记录上，收件时随域名解析一起读取：收件人没有邮箱、别名或分发列表时，
+// 投递到指定的通配邮箱，或以收件地址为域名所有者自动创建邮箱。
+type CatchAllService struct {
+	store     domain.Store
+	mailboxes *MailboxService
+	authz     *Authorizer
+}
+
+// NewCatchAllService 创建通配收件服务
+func NewCatchAllService(store domain.Store, mailboxes *MailboxService) *CatchAllService {
+	return &CatchAllService{
+		store:     store,
+		mailboxes: mailboxes,
+		authz:     NewAuthorizer(store),
+	}
+}
+
+// ConfigureUserDomain 设置用户域名的通配收件（仅已验证的独享/通配模式域名，通配邮箱须归用户所有）
+//
+// 开启时域名切换为通配模式，关闭时切回独享模式。
+func (s *CatchAllService) ConfigureUserDomain(ctx context.Context, domainID, userID string, settings domain.CatchAllSettings) (*domain.UserDomain, error) {
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
+	if err != nil {
+		return nil, ErrDomainNotFound
+	}
+	if !s.authz.Can(ctx, userID, userDomain.UserID, userDomain.OrgID, ActionManage) {
+		return nil, ErrNotDomainOwner
+	}
+	if settings.Enabled && (userDomain.Status != domain.DomainStatusVerified || userDomain.Mode == domain.DomainModeShared) {
+		return nil, ErrCatchAllRequiresVerified
+	}
+	settings.MailboxID = strings.TrimSpace(settings.MailboxID)
+	if settings.MailboxID != "" {
+		mailbox, err := s.store.GetMailbox(ctx, settings.MailboxID)
+		if err != nil || !s.authz.CanAccessMailbox(ctx, userID, mailbox, ActionManage) {
+			return nil, ErrCatchAllMailbox
+		}
+	}
+
+	userDomain.CatchAll = settings
+	if settings.Enabled {
+		userDomain.Mode = domain.DomainModeCatchAll
+	} else if userDomain.Mode == domain.DomainModeCatchAll {
+		userDomain.Mode = domain.DomainModeExclusive
+	}
+	if err := s.store.SaveUserDomain(ctx, userDomain); err != nil {
+		return nil, err
+	}
+	return userDomain, nil
+}
+
+// Route 为域名下不存在的收件地址找到投递邮箱
+//
+// 设置了通配邮箱时返回该邮箱；否则以收件地址为域名所有者创建邮箱（宽限期内和超过邮箱数上限时不创建）。
+// 返回 ErrCatchAllUnavailable 时按收件人不存在处理。
+func (s *CatchAllService) Route(ctx context.Context, res *DomainResolution, address string) (*domain.Mailbox, error) {
+	settings := res.CatchAll()
+	if settings == nil {
+		return nil, ErrCatchAllUnavailable
+	}
+	if settings.MailboxID != "" {
+		mailbox, err := s.store.GetMailbox(ctx, settings.MailboxID)
+		if err != nil {
+			return nil, ErrCatchAllUnavailable
+		}
+		return mailbox, nil
+	}
+
+	userDomain := res.UserDomain
+	if res.State != DomainStateActive || userDomain.MailboxCount >= MaxCatchAllMailboxes {
+		return nil, ErrCatchAllUnavailable
+	}
+	localPart, _, _ := strings.Cut(address, "@")
+	mailbox, err := s.mailboxes.create(ctx, CreateMailboxInput{
+		Prefix:   localPart,
+		UserID:   &userDomain.UserID,
+		OrgID:    userDomain.OrgID,
+		IPSource: "smtp-catch-all",
+	}, res.Domain)
+	switch {
+	case errors.Is(err, ErrAddressTaken):
+		// 同一地址的并发投递已经创建
+		return s.mailboxes.GetByAddress(ctx, address)
+	case errors.Is(err, ErrPrefixInvalid):
+		return nil, ErrCatchAllUnavailable
+	}
+	return mailbox, err
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestCatchAllService(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	owner, stranger := "user-1", "user-2"
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{
+		ID: "ud-1", UserID: owner, Domain: "owned.example", Mode: domain.DomainModeShared,
+		Status: domain.DomainStatusVerified, IsActive: true,
+	}))
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-own", Address: "inbox@owned.example", Domain: "owned.example", UserID: &owner, CreatedAt: time.Now(),
+	}))
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-other", Address: "x@other.example", Domain: "other.example", UserID: &stranger, CreatedAt: time.Now(),
+	}))
+	mailboxes := NewMailboxService(store, store, &config.Config{})
+	catchAll := NewCatchAllService(store, mailboxes)
+	userDomains := NewUserDomainService(store, nil)
+
+	t.Run("共享模式不能开启", func(t *testing.T) {
+		_, err := catchAll.ConfigureUserDomain(t.Context(), "ud-1", owner, domain.CatchAllSettings{Enabled: true})
+		assert.ErrorIs(t, err, ErrCatchAllRequiresVerified)
+	})
+
+	_, err := userDomains.UpdateDomainMode(t.Context(), "ud-1", owner, domain.DomainModeExclusive)
+	require.NoError(t, err)
+
+	t.Run("权限与通配邮箱校验", func(t *testing.T) {
+		_, err := catchAll.ConfigureUserDomain(t.Context(), "ud-1", stranger, domain.CatchAllSettings{Enabled: true})
+		assert.ErrorIs(t, err, ErrNotDomainOwner)
+		_, err = catchAll.ConfigureUserDomain(t.Context(), "ud-1", owner, domain.CatchAllSettings{Enabled: true, MailboxID: "mb-other"})
+		assert.ErrorIs(t, err, ErrCatchAllMailbox)
+	})
+
+	t.Run("投递到通配邮箱", func(t *testing.T) {
+		userDomain, err := catchAll.ConfigureUserDomain(t.Context(), "ud-1", owner, domain.CatchAllSettings{Enabled: true, MailboxID: "mb-own"})
+		require.NoError(t, err)
+		assert.Equal(t, domain.DomainModeCatchAll, userDomain.Mode)
+
+		target, err := catchAll.Route(t.Context(), ResolveDomain(t.Context(), store, "owned.example"), "anything@owned.example")
+		require.NoError(t, err)
+		assert.Equal(t, "mb-own", target.ID)
+	})
+
+	t.Run("未指定通配邮箱时为所有者自动创建", func(t *testing.T) {
+		_, err := catchAll.ConfigureUserDomain(t.Context(), "ud-1", owner, domain.CatchAllSettings{Enabled: true})
+		require.NoError(t, err)
+
+		created, err := catchAll.Route(t.Context(), ResolveDomain(t.Context(), store, "owned.example"), "new.user@owned.example")
+		require.NoError(t, err)
+		assert.Equal(t, "new.user@owned.example", created.Address)
+		require.NotNil(t, created.UserID)
+		assert.Equal(t, owner, *created.UserID)
+
+		// 已存在的地址（并发投递）返回同一个邮箱
+		again, err := catchAll.Route(t.Context(), ResolveDomain(t.Context(), store, "owned.example"), "new.user@owned.example")
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, again.ID)
+	})
+
+	t.Run("关闭或切换模式后失效", func(t *testing.T) {
+		userDomain, err := catchAll.ConfigureUserDomain(t.Context(), "ud-1", owner, domain.CatchAllSettings{})
+		require.NoError(t, err)
+		assert.Equal(t, domain.DomainModeExclusive, userDomain.Mode)
+		assert.Nil(t, ResolveDomain(t.Context(), store, "owned.example").CatchAll())
+
+		// 通过模式切换同样可以开关
+		_, err = userDomains.UpdateDomainMode(t.Context(), "ud-1", owner, domain.DomainModeCatchAll)
+		require.NoError(t, err)
+		assert.NotNil(t, ResolveDomain(t.Context(), store, "owned.example").CatchAll())
+		_, err = userDomains.UpdateDomainMode(t.Context(), "ud-1", owner, domain.DomainModeShared)
+		require.NoError(t, err)
+		assert.Nil(t, ResolveDomain(t.Context(), store, "owned.example").CatchAll())
+		_, err = catchAll.Route(t.Context(), ResolveDomain(t.Context(), store, "owned.example"), "late@owned.example")
+		assert.ErrorIs(t, err, ErrCatchAllUnavailable)
+	})
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// clamavChunkSize INSTREAM 每个数据块的大小
+const clamavChunkSize = 64 << 10
+
+// ClamAVScanner 通过 clamd TCP 接口（INSTREAM 命令）扫描附件
+type ClamAVScanner struct {
+	address string
+	dialer  net.Dialer
+}
+
+// NewClamAVScanner 创建 clamd 扫描器（超时由调用方的 ctx 控制）
+func NewClamAVScanner(address string) *ClamAVScanner {
+	return &ClamAVScanner{address: address}
+}
+
+// Name 扫描服务名称
+func (c *ClamAVScanner) Name() string { return ScanProviderClamAV }
+
+// Scan 按 INSTREAM 协议分块发送内容：每块以 4 字节大端长度开头，长度为 0 的块表示结束
+//
+// clamd 返回 "stream: OK"、"stream: <威胁名> FOUND" 或以 ERROR 结尾的错误（如超出 StreamMaxLength）。
+func (c *ClamAVScanner) Scan(ctx context.Context, content io.Reader) (string, error) {
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return "", fmt.Errorf("clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := c.stream(conn, content); err != nil {
+		return "", fmt.Errorf("clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return "", fmt.Errorf("clamd: read reply: %w", err)
+	}
+	return parseClamAVReply(reply)
+}
+
+// stream 发送 INSTREAM 命令和内容
+func (c *ClamAVScanner) stream(w io.Writer, content io.Reader) error {
+	if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+clamavChunkSize)
+	for {
+		n, err := io.ReadFull(content, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parseClamAVReply 解析 clamd 的扫描结果，返回威胁名称（未检出时为空）
+func parseClamAVReply(reply string) (string, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	result := reply
+	if i := strings.Index(reply, ": "); i >= 0 {
+		result = reply[i+2:]
+	}
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	default:
+		return "", fmt.Errorf("clamd: %s", reply)
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+)
+
+// fakeClamd 按 INSTREAM 协议接收内容，包含 EICAR 特征串时报告威胁
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				command, err := r.ReadString(0)
+				if err != nil || command != "zINSTREAM\x00" {
+					io.WriteString(conn, "UNKNOWN COMMAND\x00")
+					return
+				}
+				var content bytes.Buffer
+				for {
+					var size uint32
+					if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+						return
+					}
+				}
+				if bytes.Contains(content.Bytes(), []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")) {
+					io.WriteString(conn, "stream: Eicar-Test-Signature FOUND\x00")
+					return
+				}
+				io.WriteString(conn, "stream: OK\x00")
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestClamAVScanner(t *testing.T) {
+	scanner := NewClamAVScanner(fakeClamd(t))
+
+	t.Run("干净内容", func(t *testing.T) {
+		threat, err := scanner.Scan(t.Context(), strings.NewReader("hello"))
+		require.NoError(t, err)
+		assert.Empty(t, threat)
+	})
+
+	t.Run("跨多个数据块检出威胁", func(t *testing.T) {
+		content := append(bytes.Repeat([]byte("x"), clamavChunkSize+10), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE"...)
+		threat, err := scanner.Scan(t.Context(), bytes.NewReader(content))
+		require.NoError(t, err)
+		assert.Equal(t, "Eicar-Test-Signature", threat)
+	})
+
+	t.Run("解析错误响应", func(t *testing.T) {
+		_, err := parseClamAVReply("INSTREAM size limit exceeded. ERROR\x00")
+		assert.Error(t, err)
+	})
+
+	t.Run("扫描服务不可用时按 fail-open 处理", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		attachments := []*domain.Attachment{{Content: []byte("hello")}}
+		cfg := config.ScanConfig{Timeout: time.Second, FailOpen: true}
+		infected, err := NewAttachmentScanService(NewClamAVScanner(addr), cfg).Scan(t.Context(), attachments)
+		require.NoError(t, err)
+		assert.False(t, infected)
+
+		cfg.FailOpen = false
+		_, err = NewAttachmentScanService(NewClamAVScanner(addr), cfg).Scan(t.Context(), attachments)
+		assert.ErrorIs(t, err, ErrScannerUnavailable)
+	})
+}
@@ -1,9 +1,14 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"tempmail/backend/internal/config"
 	"tempmail/backend/internal/domain"
 	"tempmail/backend/internal/storage"
 )
@@ -15,40 +20,99 @@ var (
 	ErrInvalidConfig = errors.New("invalid config")
 )
 
+// maxCapturedHeaders 收信时最多保存的头数量
+const maxCapturedHeaders = 20
+
 // ConfigService 系统配置服务
 type ConfigService struct {
 	store storage.Store
+
+	// 运行时配置快照（维护模式等需要在每个请求上读取的开关）
+	mu          sync.RWMutex
+	maintenance domain.MaintenanceConfig
+	onRefresh   []func(config *domain.SystemConfig) // 刷新运行时配置后的回调
+
+	capturedHeaders        []string // 系统配置中的头名单（nil 表示使用启动配置）
+	defaultCapturedHeaders []string // 启动配置的头名单
+
+	startupMailbox *config.MailboxConfig // 启动配置的邮箱设置（系统配置未保存过时使用）
+
+	guestWebhooks domain.GuestWebhookConfig      // 邮箱 Webhook 限制
+	retention     domain.RetentionPolicyConfig   // 按用户等级的邮件保留时长
+	inbound       domain.InboundProtectionConfig // SMTP 收信限流和灰名单
+
+	requireAdminTwoFactor bool // 管理员必须启用两步验证
 }
 
 // NewConfigService 创建配置服务
 func NewConfigService(store storage.Store) *ConfigService {
-	return &ConfigService{
+	s := &ConfigService{
 		store: store,
 	}
+	s.RefreshRuntimeConfig(context.Background())
+	return s
 }
 
 // GetSystemConfig 获取系统配置
-func (s *ConfigService) GetSystemConfig() (*domain.SystemConfig, error) {
-	config, err := s.store.GetSystemConfig()
+func (s *ConfigService) GetSystemConfig(ctx context.Context) (*domain.SystemConfig, error) {
+	return s.load(ctx)
+}
+
+// load 从存储读取系统配置，未保存过时以启动配置补齐
+func (s *ConfigService) load(ctx context.Context) (*domain.SystemConfig, error) {
+	config, err := s.store.GetSystemConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if config.UpdatedBy == "" {
+		s.withStartupDefaults(config)
+	}
 	return config, nil
 }
 
+// withStartupDefaults 用启动配置覆盖内置默认值
+//
+// 管理员保存前存储返回的是内置默认值（允许域名 temp.mail 等），直接下发会覆盖部署时的配置，
+// 因此邮箱设置以启动配置为准，全局限流保持关闭（与升级前一致）。
+func (s *ConfigService) withStartupDefaults(config *domain.SystemConfig) {
+	s.mu.RLock()
+	startup := s.startupMailbox
+	s.mu.RUnlock()
+	if startup == nil {
+		return
+	}
+	config.Mailbox.AllowedDomains = slices.Clone(startup.AllowedDomains)
+	config.Mailbox.DefaultTTL = startup.DefaultTTL.String()
+	if startup.MaxPerIP > 0 {
+		config.Mailbox.MaxPerIP = startup.MaxPerIP
+	}
+	config.RateLimit.Enabled = false
+}
+
+// SetStartupDefaults 设置启动配置的邮箱设置（系统配置未保存过时使用）
+func (s *ConfigService) SetStartupDefaults(mailbox config.MailboxConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.startupMailbox = &mailbox
+}
+
 // UpdateSystemConfigInput 更新系统配置输入
 type UpdateSystemConfigInput struct {
-	SMTP      *domain.SMTPConfig      `json:"smtp,omitempty"`
-	Mailbox   *domain.MailboxConfig   `json:"mailbox,omitempty"`
-	RateLimit *domain.RateLimitConfig `json:"rateLimit,omitempty"`
-	Security  *domain.SecurityConfig  `json:"security,omitempty"`
-	UpdatedBy string                  `json:"-"` // 更新者用户ID
+	SMTP          *domain.SMTPConfig              `json:"smtp,omitempty"`
+	Mailbox       *domain.MailboxConfig           `json:"mailbox,omitempty"`
+	RateLimit     *domain.RateLimitConfig         `json:"rateLimit,omitempty"`
+	Security      *domain.SecurityConfig          `json:"security,omitempty"`
+	Idle          *domain.IdlePolicyConfig        `json:"idle,omitempty"`
+	GuestWebhooks *domain.GuestWebhookConfig      `json:"guestWebhooks,omitempty"`
+	Retention     *domain.RetentionPolicyConfig   `json:"retention,omitempty"`
+	Inbound       *domain.InboundProtectionConfig `json:"inbound,omitempty"`
+	UpdatedBy     string                          `json:"-"` // 更新者用户ID
 }
 
 // UpdateSystemConfig 更新系统配置（需要超级管理员权限）
-func (s *ConfigService) UpdateSystemConfig(input UpdateSystemConfigInput) (*domain.SystemConfig, error) {
+func (s *ConfigService) UpdateSystemConfig(ctx context.Context, input UpdateSystemConfigInput) (*domain.SystemConfig, error) {
 	// 获取当前配置
-	config, err := s.store.GetSystemConfig()
+	config, err := s.load(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -62,8 +126,8 @@ func (s *ConfigService) UpdateSystemConfig(input UpdateSystemConfigInput) (*doma
 		if input.SMTP.Domain == "" {
 			return nil, errors.New("SMTP Domain不能为空")
 		}
-		if input.SMTP.MaxSize <= 0 {
-			return nil, errors.New("SMTP MaxSize必须大于0")
+		if input.SMTP.MaxSize <= 0 || input.SMTP.MaxSize > domain.MaxSMTPMessageSize {
+			return nil, errors.New("SMTP MaxSize必须在1到100MB之间")
 		}
 		config.SMTP = *input.SMTP
 	}
@@ -83,6 +147,14 @@ func (s *ConfigService) UpdateSystemConfig(input UpdateSystemConfigInput) (*doma
 		if len(input.Mailbox.AllowedDomains) == 0 {
 			return nil, errors.New("Mailbox AllowedDomains不能为空")
 		}
+		if len(input.Mailbox.CapturedHeaders) > maxCapturedHeaders {
+			return nil, errors.New("Mailbox CapturedHeaders不能超过20个")
+		}
+		for _, name := range input.Mailbox.CapturedHeaders {
+			if !domain.ValidHeaderName(name) {
+				return nil, errors.New("Mailbox CapturedHeaders包含无效的头名称")
+			}
+		}
 		config.Mailbox = *input.Mailbox
 	}
 
@@ -118,30 +190,313 @@ func (s *ConfigService) UpdateSystemConfig(input UpdateSystemConfigInput) (*doma
 		if input.Security.MaxLoginAttempts <= 0 {
 			return nil, errors.New("Security MaxLoginAttempts必须大于0")
 		}
+		// 开启管理员两步验证要求前操作者自己必须已启用，否则会把自己挡在管理接口之外
+		if input.Security.RequireAdminTwoFactor && !config.Security.RequireAdminTwoFactor && input.UpdatedBy != "" {
+			if user, err := s.store.GetUserByID(ctx, input.UpdatedBy); err != nil || !user.TOTPEnabled {
+				return nil, errors.New("Security RequireAdminTwoFactor需要先为当前账户启用两步验证")
+			}
+		}
 		config.Security = *input.Security
 	}
 
+	if input.Idle != nil {
+		// 验证闲置策略
+		switch input.Idle.Action {
+		case domain.IdleActionNone, domain.IdleActionWarn, domain.IdleActionShortenTTL:
+		default:
+			return nil, errors.New("Idle Action必须为 none、warn 或 shorten-ttl")
+		}
+		idleAfter, err := time.ParseDuration(input.Idle.IdleAfter)
+		if err != nil {
+			return nil, errors.New("Idle IdleAfter格式无效")
+		}
+		if idleAfter < 24*time.Hour {
+			return nil, errors.New("Idle IdleAfter不能小于24h")
+		}
+		warnBefore, err := time.ParseDuration(input.Idle.WarnBefore)
+		if err != nil {
+			return nil, errors.New("Idle WarnBefore格式无效")
+		}
+		if warnBefore <= 0 {
+			return nil, errors.New("Idle WarnBefore必须大于0")
+		}
+		config.Idle = *input.Idle
+	}
+
+	if input.GuestWebhooks != nil {
+		// 验证邮箱 Webhook 限制
+		if input.GuestWebhooks.MaxPerMailbox <= 0 || input.GuestWebhooks.MaxPerMailbox > 20 {
+			return nil, errors.New("GuestWebhooks MaxPerMailbox必须在1到20之间")
+		}
+		if len(input.GuestWebhooks.RetrySchedule) > 5 {
+			return nil, errors.New("GuestWebhooks RetrySchedule不能超过5项")
+		}
+		for _, item := range input.GuestWebhooks.RetrySchedule {
+			if d, err := time.ParseDuration(item); err != nil || d <= 0 {
+				return nil, errors.New("GuestWebhooks RetrySchedule格式无效")
+			}
+		}
+		config.GuestWebhooks = *input.GuestWebhooks
+		if config.GuestWebhooks.RetrySchedule == nil {
+			config.GuestWebhooks.RetrySchedule = []string{} // 显式不重试
+		}
+	}
+
+	if input.Retention != nil {
+		// 验证邮件保留时长
+		if err := validateRetention(*input.Retention); err != nil {
+			return nil, err
+		}
+		config.Retention = *input.Retention
+	}
+
+	if input.Inbound != nil {
+		// 验证收信保护配置
+		if err := validateInbound(*input.Inbound); err != nil {
+			return nil, err
+		}
+		config.Inbound = *input.Inbound
+	}
+
 	// 设置更新者
 	config.UpdatedBy = input.UpdatedBy
 	config.UpdatedAt = time.Now()
 
 	// 保存配置
-	if err := s.store.SaveSystemConfig(config); err != nil {
+	if err := s.store.SaveSystemConfig(ctx, config); err != nil {
 		return nil, err
 	}
 
+	s.apply(config)
 	return config, nil
 }
 
 // ResetSystemConfig 重置系统配置为默认值（需要超级管理员权限）
-func (s *ConfigService) ResetSystemConfig(updatedBy string) (*domain.SystemConfig, error) {
+//
+// 维护模式开关和 JWT 签名密钥不受重置影响，避免在维护期间误操作导致写入提前恢复、
+// 或所有用户被迫重新登录。
+func (s *ConfigService) ResetSystemConfig(ctx context.Context, updatedBy string) (*domain.SystemConfig, error) {
+	current, err := s.store.GetSystemConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	config := domain.DefaultSystemConfig()
+	s.withStartupDefaults(config)
+	config.Maintenance = s.Maintenance()
+	config.JWTKeys = current.JWTKeys
 	config.UpdatedBy = updatedBy
 	config.UpdatedAt = time.Now()
 
-	if err := s.store.SaveSystemConfig(config); err != nil {
+	if err := s.store.SaveSystemConfig(ctx, config); err != nil {
 		return nil, err
 	}
 
+	s.apply(config)
 	return config, nil
 }
+
+// SetMaintenanceInput 切换维护模式输入
+type SetMaintenanceInput struct {
+	ReadOnly  bool   `json:"readOnly"`
+	Message   string `json:"message"`
+	UpdatedBy string `json:"-"` // 操作者用户ID
+}
+
+// SetMaintenance 切换只读维护模式（需要超级管理员权限）
+//
+// 新状态写入系统配置后立即在本实例生效，其他实例通过 WatchRuntimeConfig 拉取。
+func (s *ConfigService) SetMaintenance(ctx context.Context, input SetMaintenanceInput) (*domain.MaintenanceConfig, error) {
+	message := strings.TrimSpace(input.Message)
+	if len(message) > 500 {
+		return nil, errors.New("维护说明不能超过500个字符")
+	}
+
+	config, err := s.store.GetSystemConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	config.Maintenance = domain.MaintenanceConfig{
+		ReadOnly:  input.ReadOnly,
+		Message:   message,
+		UpdatedAt: &now,
+		UpdatedBy: input.UpdatedBy,
+	}
+	if !input.ReadOnly {
+		config.Maintenance.Message = ""
+	}
+
+	if err := s.store.SaveSystemConfig(ctx, config); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.maintenance = config.Maintenance
+	s.mu.Unlock()
+
+	result := config.Maintenance
+	return &result, nil
+}
+
+// Maintenance 返回当前维护模式状态（内存快照）
+func (s *ConfigService) Maintenance() domain.MaintenanceConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maintenance
+}
+
+// MaintenanceState 返回是否只读及维护说明，供 HTTP 中间件与 SMTP 后端使用
+func (s *ConfigService) MaintenanceState() (bool, string) {
+	m := s.Maintenance()
+	return m.ReadOnly, m.Message
+}
+
+// RequireAdminTwoFactor 管理员是否必须启用两步验证（内存快照），供管理员权限中间件使用
+func (s *ConfigService) RequireAdminTwoFactor() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.requireAdminTwoFactor
+}
+
+// SetDefaultCapturedHeaders 设置启动配置的头名单（系统配置未设置名单时使用）
+func (s *ConfigService) SetDefaultCapturedHeaders(names []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultCapturedHeaders = names
+}
+
+// CapturedHeaders 返回收信时保存的头名单（内存快照），供 SMTP 后端使用
+func (s *ConfigService) CapturedHeaders() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.capturedHeaders != nil {
+		return s.capturedHeaders
+	}
+	return s.defaultCapturedHeaders
+}
+
+// GuestWebhooks 返回邮箱 Webhook 限制（内存快照），供 Webhook 服务使用
+func (s *ConfigService) GuestWebhooks() domain.GuestWebhookConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.guestWebhooks.WithDefaults()
+}
+
+// MessageRetention 返回按用户等级的邮件保留时长（内存快照），供保留清理任务使用
+func (s *ConfigService) MessageRetention() domain.RetentionPolicyConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.retention
+}
+
+// InboundProtection 返回 SMTP 收信保护配置（内存快照），供 SMTP 后端在每个连接和命令上读取
+func (s *ConfigService) InboundProtection() domain.InboundProtectionConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.inbound
+}
+
+// validateInbound 校验收信保护配置：限额不为负，灰名单时长为空或不短于 1 秒
+func validateInbound(inbound domain.InboundProtectionConfig) error {
+	if inbound.MaxConnectionsPerIP < 0 || inbound.MaxConnectionsPerIP > 1000 {
+		return errors.New("Inbound MaxConnectionsPerIP必须在0到1000之间")
+	}
+	if inbound.SenderDomainPerMinute < 0 || inbound.SenderDomainPerMinute > 100000 {
+		return errors.New("Inbound SenderDomainPerMinute必须在0到100000之间")
+	}
+	for _, value := range []string{inbound.Greylisting.Delay, inbound.Greylisting.Expiry} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < time.Second {
+			return errors.New("Inbound Greylisting 时长格式无效或小于1s")
+		}
+	}
+	return nil
+}
+
+// validateRetention 校验保留时长：等级必须有效，时长为空或不短于 1 分钟
+func validateRetention(policy domain.RetentionPolicyConfig) error {
+	check := func(value string) error {
+		if value == "" {
+			return nil
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < time.Minute {
+			return errors.New("Retention 时长格式无效或小于1m")
+		}
+		return nil
+	}
+	if err := check(policy.Guest); err != nil {
+		return err
+	}
+	for tier, value := range policy.Tiers {
+		if !slices.Contains(domain.UserTiers, tier) {
+			return errors.New("Retention Tiers包含无效的用户等级")
+		}
+		if err := check(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RefreshRuntimeConfig 从存储重新加载运行时配置快照
+func (s *ConfigService) RefreshRuntimeConfig(ctx context.Context) error {
+	config, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	s.apply(config)
+	return nil
+}
+
+// apply 更新运行时配置快照并通知回调
+//
+// 本实例保存配置后立即调用，其他实例由 WatchRuntimeConfig 轮询时调用。
+// 回调在每次轮询时都会收到完整配置，需要自行保证重复应用无副作用。
+func (s *ConfigService) apply(config *domain.SystemConfig) {
+	s.mu.Lock()
+	s.maintenance = config.Maintenance
+	s.capturedHeaders = config.Mailbox.CapturedHeaders
+	s.guestWebhooks = config.GuestWebhooks.WithDefaults()
+	s.retention = config.Retention
+	s.inbound = config.Inbound
+	s.requireAdminTwoFactor = config.Security.RequireAdminTwoFactor
+	hooks := s.onRefresh
+	s.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(config)
+	}
+}
+
+// OnRuntimeRefresh 注册运行时配置变化回调（如同步 JWT 签名密钥、允许域名、邮件大小上限和限流）
+func (s *ConfigService) OnRuntimeRefresh(hook func(config *domain.SystemConfig)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRefresh = append(s.onRefresh, hook)
+}
+
+// WatchRuntimeConfig 定期从共享存储刷新运行时配置，直到 ctx 结束
+//
+// 多实例部署时配置保存在共享存储（Redis/数据库）中，
+// 其他实例切换维护模式后本实例在一个轮询周期内生效。
+func (s *ConfigService) WatchRuntimeConfig(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = s.RefreshRuntimeConfig(ctx)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestConfigService_Maintenance(t *testing.T) {
+	t.Run("切换维护模式立即生效", func(t *testing.T) {
+		store := memory.NewStore(24 * time.Hour)
+		svc := NewConfigService(store)
+
+		readOnly, _ := svc.MaintenanceState()
+		assert.False(t, readOnly)
+
+		m, err := svc.SetMaintenance(t.Context(), SetMaintenanceInput{
+			ReadOnly:  true,
+			Message:   "upgrading database",
+			UpdatedBy: "admin-1",
+		})
+		require.NoError(t, err)
+		assert.True(t, m.ReadOnly)
+		assert.Equal(t, "admin-1", m.UpdatedBy)
+		assert.NotNil(t, m.UpdatedAt)
+
+		readOnly, message := svc.MaintenanceState()
+		assert.True(t, readOnly)
+		assert.Equal(t, "upgrading database", message)
+
+		_, err = svc.SetMaintenance(t.Context(), SetMaintenanceInput{ReadOnly: false, Message: "ignored"})
+		require.NoError(t, err)
+		readOnly, message = svc.MaintenanceState()
+		assert.False(t, readOnly)
+		assert.Empty(t, message)
+	})
+
+	t.Run("共享存储的其他实例通过轮询获取状态", func(t *testing.T) {
+		store := memory.NewStore(24 * time.Hour)
+		primary := NewConfigService(store)
+		secondary := NewConfigService(store)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go secondary.WatchRuntimeConfig(ctx, 10*time.Millisecond)
+
+		_, err := primary.SetMaintenance(ctx, SetMaintenanceInput{ReadOnly: true, Message: "incident"})
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool {
+			readOnly, message := secondary.MaintenanceState()
+			return readOnly && message == "incident"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("重置配置保留维护状态", func(t *testing.T) {
+		store := memory.NewStore(24 * time.Hour)
+		svc := NewConfigService(store)
+
+		_, err := svc.SetMaintenance(t.Context(), SetMaintenanceInput{ReadOnly: true})
+		require.NoError(t, err)
+
+		cfg, err := svc.ResetSystemConfig(t.Context(), "admin-1")
+		require.NoError(t, err)
+		assert.True(t, cfg.Maintenance.ReadOnly)
+	})
+}
+
+func TestJWTKeyService_Rotate(t *testing.T) {
+	const secret = "configured-secret-0123456789abcdefghij"
+	store := memory.NewStore(24 * time.Hour)
+
+	// 两个实例共享存储，各自持有 JWT 管理器
+	newInstance := func() (*jwtpkg.Manager, *ConfigService, *JWTKeyService) {
+		manager := jwtpkg.NewManager(secret, "tempmail", 15*time.Minute, 7*24*time.Hour)
+		configs := NewConfigService(store)
+		keys := NewJWTKeyService(store, manager)
+		require.NoError(t, keys.Load(t.Context()))
+		configs.OnRuntimeRefresh(keys.Apply)
+		return manager, configs, keys
+	}
+	managerA, configsA, keysA := newInstance()
+	managerB, configsB, _ := newInstance()
+
+	before, err := managerA.GenerateTokenPair(t.Context(), "user-1", "u@example.com", "free")
+	require.NoError(t, err)
+
+	status, err := keysA.Rotate(t.Context(), "admin-1")
+	require.NoError(t, err)
+	assert.Equal(t, jwtpkg.KeyID(secret), status.PreviousKeyID)
+	assert.NotEqual(t, status.PreviousKeyID, status.CurrentKeyID)
+	require.NotNil(t, status.PreviousRetireAt)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), *status.PreviousRetireAt, time.Minute)
+
+	after, err := managerA.GenerateTokenPair(t.Context(), "user-1", "u@example.com", "free")
+	require.NoError(t, err)
+
+	t.Run("其他实例同步前不认识新密钥", func(t *testing.T) {
+		_, err := managerB.ValidateToken(after.AccessToken)
+		assert.ErrorIs(t, err, jwtpkg.ErrInvalidToken)
+	})
+
+	t.Run("运行时配置刷新后各实例收敛", func(t *testing.T) {
+		require.NoError(t, configsB.RefreshRuntimeConfig(t.Context()))
+		for _, token := range []string{before.AccessToken, after.AccessToken} {
+			_, err := managerB.ValidateToken(token)
+			assert.NoError(t, err)
+		}
+		currentA, _ := managerA.Keys()
+		currentB, _ := managerB.Keys()
+		assert.Equal(t, currentA, currentB)
+	})
+
+	t.Run("密钥不出现在配置接口响应中且重置后保留", func(t *testing.T) {
+		config, err := configsA.GetSystemConfig(t.Context())
+		require.NoError(t, err)
+		require.NotNil(t, config.JWTKeys)
+		assert.Nil(t, config.WithoutSecrets().JWTKeys)
+
+		reset, err := configsA.ResetSystemConfig(t.Context(), "admin-1")
+		require.NoError(t, err)
+		require.NotNil(t, reset.JWTKeys)
+		assert.Equal(t, status.CurrentKeyID, reset.JWTKeys.Current.ID)
+	})
+
+	t.Run("重启后从系统配置加载轮换后的密钥", func(t *testing.T) {
+		managerC, _, keysC := newInstance()
+		assert.Equal(t, status.CurrentKeyID, keysC.Status().CurrentKeyID)
+		_, err := managerC.ValidateToken(before.AccessToken)
+		assert.NoError(t, err)
+	})
+}
+
+func TestConfigService_Retention(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	svc := NewConfigService(store)
+
+	for name, policy := range map[string]domain.RetentionPolicyConfig{
+		"无效的时长": {Guest: "soon"},
+		"时长过短":  {Tiers: map[domain.UserTier]string{domain.TierFree: "30s"}},
+		"无效的等级": {Tiers: map[domain.UserTier]string{"gold": "1h"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.UpdateSystemConfig(t.Context(), UpdateSystemConfigInput{Retention: &policy})
+			assert.Error(t, err)
+		})
+	}
+
+	policy := domain.RetentionPolicyConfig{Tiers: map[domain.UserTier]string{domain.TierFree: "1h", domain.TierPro: "168h"}}
+	_, err := svc.UpdateSystemConfig(t.Context(), UpdateSystemConfigInput{Retention: &policy})
+	require.NoError(t, err)
+
+	free, pro := domain.TierFree, domain.TierPro
+	assert.Equal(t, time.Hour, svc.MessageRetention().For(&free))
+	assert.Equal(t, 7*24*time.Hour, svc.MessageRetention().For(&pro))
+	assert.Zero(t, svc.MessageRetention().For(nil), "未设置游客时长时不限制")
+
+	// 其他实例刷新运行时配置后生效
+	other := NewConfigService(store)
+	assert.Equal(t, time.Hour, other.MessageRetention().For(&free))
+}
+
+func TestConfigService_InboundProtection(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	svc := NewConfigService(store)
+
+	for name, inbound := range map[string]domain.InboundProtectionConfig{
+		"连接数为负":   {MaxConnectionsPerIP: -1},
+		"发件速率过大":  {SenderDomainPerMinute: 1000000},
+		"无效的等待时长": {Greylisting: domain.GreylistingConfig{Enabled: true, Delay: "soon"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.UpdateSystemConfig(t.Context(), UpdateSystemConfigInput{Inbound: &inbound})
+			assert.Error(t, err)
+		})
+	}
+
+	inbound := domain.InboundProtectionConfig{
+		MaxConnectionsPerIP:   5,
+		SenderDomainPerMinute: 100,
+		Greylisting:           domain.GreylistingConfig{Enabled: true},
+	}
+	_, err := svc.UpdateSystemConfig(t.Context(), UpdateSystemConfigInput{Inbound: &inbound})
+	require.NoError(t, err)
+	assert.Equal(t, 5, svc.InboundProtection().MaxConnectionsPerIP)
+	assert.Equal(t, domain.DefaultGreylistDelay, svc.InboundProtection().Greylisting.DelayDuration(), "未设置时使用默认等待时长")
+
+	// 其他实例刷新运行时配置后生效
+	other := NewConfigService(store)
+	assert.Equal(t, 100, other.InboundProtection().SenderDomainPerMinute)
+}
+
+func TestConfigService_RequireAdminTwoFactor(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	svc := NewConfigService(store)
+	admin := &domain.User{ID: "admin-1", Email: "admin@example.com", Username: "admin", Role: domain.RoleSuper, IsActive: true}
+	require.NoError(t, store.CreateUser(t.Context(), admin))
+
+	security := domain.DefaultSystemConfig().Security
+	security.JWTRefreshExpiry = "168h" // 默认值 7d 不是有效的 time.Duration
+	security.RequireAdminTwoFactor = true
+
+	_, err := svc.UpdateSystemConfig(t.Context(), UpdateSystemConfigInput{Security: &security, UpdatedBy: admin.ID})
+	assert.Error(t, err, "操作者未启用两步验证时不能开启")
+	assert.False(t, svc.RequireAdminTwoFactor())
+
+	admin.TOTPEnabled = true
+	require.NoError(t, store.UpdateUser(t.Context(), admin))
+	_, err = svc.UpdateSystemConfig(t.Context(), UpdateSystemConfigInput{Security: &security, UpdatedBy: admin.ID})
+	require.NoError(t, err)
+	assert.True(t, svc.RequireAdminTwoFactor())
+
+	// 其他实例刷新运行时配置后生效
+	other := NewConfigService(store)
+	assert.True(t, other.RequireAdminTwoFactor())
+}
+
+func TestConfigService_RuntimeNotify(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"corp.example"}, DefaultTTL: time.Hour, MaxPerIP: 5}}
+	svc := NewConfigService(store)
+	svc.SetStartupDefaults(cfg.Mailbox)
+	mailboxes := NewMailboxService(store, store, cfg)
+	svc.OnRuntimeRefresh(mailboxes.ApplySystemConfig)
+
+	t.Run("管理员保存前以启动配置为准", func(t *testing.T) {
+		require.NoError(t, svc.RefreshRuntimeConfig(t.Context()))
+		current, err := svc.GetSystemConfig(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"corp.example"}, current.Mailbox.AllowedDomains)
+		assert.Equal(t, "1h0m0s", current.Mailbox.DefaultTTL)
+		assert.Equal(t, 5, current.Mailbox.MaxPerIP)
+		assert.False(t, current.RateLimit.Enabled, "未保存时不启用全局限流")
+
+		assert.Equal(t, []string{"corp.example"}, mailboxes.AllowedDomains())
+		assert.Equal(t, time.Hour, mailboxes.DefaultTTL())
+	})
+
+	t.Run("保存后立即通知并生效", func(t *testing.T) {
+		_, err := svc.UpdateSystemConfig(t.Context(), UpdateSystemConfigInput{
+			Mailbox:   &domain.MailboxConfig{DefaultTTL: "2h", MaxPerIP: 3, AllowedDomains: []string{"new.example"}},
+			UpdatedBy: "admin-1",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"new.example"}, mailboxes.AllowedDomains())
+		assert.Equal(t, 2*time.Hour, mailboxes.DefaultTTL())
+
+		_, err = mailboxes.Create(t.Context(), CreateMailboxInput{Domain: "corp.example"})
+		assert.ErrorIs(t, err, ErrDomainNotAllowed)
+		mb, err := mailboxes.Create(t.Context(), CreateMailboxInput{})
+		require.NoError(t, err)
+		assert.Equal(t, "new.example", mb.Domain)
+	})
+
+	t.Run("其他实例轮询时收到变化", func(t *testing.T) {
+		other := NewConfigService(store)
+		var got *domain.SystemConfig
+		other.OnRuntimeRefresh(func(config *domain.SystemConfig) { got = config })
+
+		smtpConfig := domain.DefaultSystemConfig().SMTP
+		smtpConfig.MaxSize = 20 << 20
+		_, err := svc.UpdateSystemConfig(t.Context(), UpdateSystemConfigInput{SMTP: &smtpConfig, UpdatedBy: "admin-1"})
+		require.NoError(t, err)
+		assert.Nil(t, got)
+
+		require.NoError(t, other.RefreshRuntimeConfig(t.Context()))
+		require.NotNil(t, got)
+		assert.Equal(t, int64(20<<20), got.SMTP.MaxSize)
+		assert.Equal(t, []string{"new.example"}, got.Mailbox.AllowedDomains, "之前保存的设置保留")
+	})
+
+	t.Run("邮件大小上限不能超过协议层兜底值", func(t *testing.T) {
+		smtpConfig := domain.DefaultSystemConfig().SMTP
+		smtpConfig.MaxSize = domain.MaxSMTPMessageSize + 1
+		_, err := svc.UpdateSystemConfig(t.Context(), UpdateSystemConfigInput{SMTP: &smtpConfig, UpdatedBy: "admin-1"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,486 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+var (
+	ErrListNotFound       = errors.New("distribution list not found")
+	ErrListAddressInvalid = errors.New("invalid distribution list address")
+	ErrListAddressTaken   = errors.New("address already in use")
+	ErrListDomainNotOwned = errors.New("list address must be on a domain you control")
+	ErrListTooLarge       = errors.New("distribution list has too many members")
+	ErrListMemberInvalid  = errors.New("member must be a mailbox or list of the same owner")
+	ErrListLoop           = errors.New("distribution list cannot include itself")
+	ErrMailboxFull        = errors.New("mailbox message quota exceeded")
+)
+
+// DefaultMaxListMembers 分发列表默认成员上限
+const DefaultMaxListMembers = 20
+
+// DistributionListService 分发列表服务
+//
+// 发往列表地址的邮件只解析、落盘一次，然后在每个成员邮箱中创建一条邮件记录。
+// 成员可以是邮箱或同一所有者的其他列表（嵌套），保存时拒绝形成环。
+type DistributionListService struct {
+	store      domain.Store
+	aliases    storage.AliasRepository // 可选：地址冲突检查
+	messages   *MessageService
+	authz      *Authorizer
+	maxMembers int
+	now        func() time.Time
+	// 成员邮箱邮件数量配额用尽通知（可选）
+	quotaNotifier QuotaExceededNotifier
+}
+
+// NewDistributionListService 创建分发列表服务
+func NewDistributionListService(store domain.Store, messages *MessageService, cfg *config.Config) *DistributionListService {
+	maxMembers := DefaultMaxListMembers
+	if cfg != nil && cfg.Mailbox.MaxListMembers > 0 {
+		maxMembers = cfg.Mailbox.MaxListMembers
+	}
+	return &DistributionListService{
+		store:      store,
+		messages:   messages,
+		authz:      NewAuthorizer(store),
+		maxMembers: maxMembers,
+		now:        time.Now,
+	}
+}
+
+// SetAliasRepository 设置别名存储，用于检查列表地址是否与别名冲突
+func (s *DistributionListService) SetAliasRepository(aliases storage.AliasRepository) {
+	s.aliases = aliases
+}
+
+// SetQuotaNotifier 设置成员邮箱邮件数量配额用尽通知
+func (s *DistributionListService) SetQuotaNotifier(notifier QuotaExceededNotifier) {
+	s.quotaNotifier = notifier
+}
+
+// CreateDistributionListInput 创建分发列表输入
+type CreateDistributionListInput struct {
+	UserID  string
+	OrgID   *string // 可选：创建为组织列表（需要组织成员身份）
+	Name    string
+	Address string
+	Members []string
+}
+
+// UpdateDistributionListInput 更新分发列表输入（nil 表示不修改）
+type UpdateDistributionListInput struct {
+	Name     *string
+	Members  []string
+	IsActive *bool
+}
+
+// Create 创建分发列表
+func (s *DistributionListService) Create(ctx context.Context, input CreateDistributionListInput) (*domain.DistributionList, error) {
+	if domain.InOrg(input.OrgID) {
+		if _, err := s.authz.RequireOrgRole(ctx, input.UserID, *input.OrgID, domain.OrgRoleMember); err != nil {
+			return nil, err
+		}
+	}
+
+	now := s.now().UTC()
+	list := &domain.DistributionList{
+		ID:        uuid.NewString(),
+		UserID:    input.UserID,
+		OrgID:     input.OrgID,
+		Name:      strings.TrimSpace(input.Name),
+		Address:   normalizeListAddress(input.Address),
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.checkAddress(ctx, list); err != nil {
+		return nil, err
+	}
+	members, err := s.validateMembers(ctx, list, input.Members)
+	if err != nil {
+		return nil, err
+	}
+	list.Members = members
+
+	if err := s.save(ctx, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Get 获取分发列表（所有者或组织成员）
+func (s *DistributionListService) Get(ctx context.Context, userID, id string) (*domain.DistributionList, error) {
+	list, err := s.store.GetDistributionList(ctx, id)
+	if err != nil {
+		return nil, ErrListNotFound
+	}
+	if !s.authz.Can(ctx, userID, list.UserID, list.OrgID, ActionRead) {
+		return nil, ErrListNotFound
+	}
+	return list, nil
+}
+
+// List 列出用户可访问的分发列表（个人及所在组织的列表）
+func (s *DistributionListService) List(ctx context.Context, userID string) ([]*domain.DistributionList, error) {
+	owned, err := s.store.ListDistributionListsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*domain.DistributionList, 0, len(owned))
+	for _, list := range owned {
+		if !domain.InOrg(list.OrgID) {
+			result = append(result, list)
+		}
+	}
+	for _, orgID := range OrgIDsForUser(ctx, s.store, userID) {
+		orgLists, err := s.store.ListDistributionListsByOrgID(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, orgLists...)
+	}
+	return result, nil
+}
+
+// Update 更新分发列表名称、成员或启用状态
+func (s *DistributionListService) Update(ctx context.Context, userID, id string, input UpdateDistributionListInput) (*domain.DistributionList, error) {
+	list, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.authz.Can(ctx, userID, list.UserID, list.OrgID, ActionWrite) {
+		return nil, ErrListNotFound
+	}
+
+	if input.Name != nil {
+		list.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Members != nil {
+		members, err := s.validateMembers(ctx, list, input.Members)
+		if err != nil {
+			return nil, err
+		}
+		list.Members = members
+	}
+	if input.IsActive != nil {
+		list.IsActive = *input.IsActive
+	}
+	list.UpdatedAt = s.now().UTC()
+
+	if err := s.save(ctx, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Delete 删除分发列表
+func (s *DistributionListService) Delete(ctx context.Context, userID, id string) error {
+	list, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !s.authz.Can(ctx, userID, list.UserID, list.OrgID, ActionWrite) {
+		return ErrListNotFound
+	}
+	// 从引用它的其他列表中移除，避免留下悬空成员
+	for _, other := range s.scopeLists(ctx, list.UserID, list.OrgID) {
+		if removeMember(other, list.ID) {
+			if err := s.store.SaveDistributionList(ctx, other); err != nil {
+				return err
+			}
+		}
+	}
+	return s.store.DeleteDistributionList(ctx, id)
+}
+
+// Deliveries 获取分发列表最近的投递报告
+func (s *DistributionListService) Deliveries(ctx context.Context, userID, id string, limit int) ([]*domain.DistributionListDelivery, error) {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return s.store.ListDistributionListDeliveries(ctx, id, limit)
+}
+
+// ResolveAddress 查找地址对应的已启用分发列表（SMTP 收件人解析使用）
+func (s *DistributionListService) ResolveAddress(ctx context.Context, address string) (*domain.DistributionList, bool) {
+	list, err := s.store.GetDistributionListByAddress(ctx, normalizeListAddress(address))
+	if err != nil || !list.IsActive {
+		return nil, false
+	}
+	return list, true
+}
+
+// Expand 展开分发列表的成员邮箱（按顺序去重，嵌套列表递归展开）
+func (s *DistributionListService) Expand(ctx context.Context, list *domain.DistributionList) []string {
+	var result []string
+	seenMailboxes := make(map[string]bool)
+	visited := map[string]bool{list.ID: true}
+
+	var walk func(members []string)
+	walk = func(members []string) {
+		for _, id := range members {
+			if seenMailboxes[id] || visited[id] {
+				continue
+			}
+			if nested, err := s.store.GetDistributionList(ctx, id); err == nil {
+				visited[id] = true
+				if nested.IsActive {
+					walk(nested.Members)
+				}
+				continue
+			}
+			seenMailboxes[id] = true
+			result = append(result, id)
+		}
+	}
+	walk(list.Members)
+	return result
+}
+
+// Deliver 将邮件投递到分发列表的每个成员邮箱，返回投递报告和创建的邮件
+//
+// 单个成员失败（如超出配额、域名过期冻结）不影响其他成员，失败原因记录在投递报告中。
+// 已删除的成员邮箱会被自动移出列表。input 中的 MailboxID 会被忽略。
+func (s *DistributionListService) Deliver(ctx context.Context, list *domain.DistributionList, input CreateMessageInput) (*domain.DistributionListDelivery, []*domain.Message) {
+	report := &domain.DistributionListDelivery{
+		ID:        uuid.NewString(),
+		ListID:    list.ID,
+		From:      input.From,
+		Subject:   input.Subject,
+		Delivered: []string{},
+		Failures:  []domain.DistributionListFailure{},
+		CreatedAt: s.now().UTC(),
+	}
+
+	var messages []*domain.Message
+	for _, mailboxID := range s.Expand(ctx, list) {
+		mailbox, err := s.store.GetMailbox(ctx, mailboxID)
+		if err != nil {
+			if errors.Is(err, storage.ErrMailboxNotFound) {
+				s.pruneMember(ctx, list, mailboxID)
+				continue
+			}
+			report.Failures = append(report.Failures, domain.DistributionListFailure{MailboxID: mailboxID, Error: err.Error()})
+			continue
+		}
+
+		message, err := s.deliverTo(ctx, mailbox, input)
+		if err != nil {
+			report.Failures = append(report.Failures, domain.DistributionListFailure{MailboxID: mailboxID, Error: err.Error()})
+			continue
+		}
+		report.Delivered = append(report.Delivered, mailboxID)
+		messages = append(messages, message)
+	}
+
+	_ = s.store.SaveDistributionListDelivery(ctx, report)
+	return report, messages
+}
+
+// PruneMailbox 将已删除的邮箱从所属范围内的分发列表中移除（邮箱删除时调用）
+func (s *DistributionListService) PruneMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	owner := ""
+	if mailbox.UserID != nil {
+		owner = *mailbox.UserID
+	}
+	if owner == "" && !domain.InOrg(mailbox.OrgID) {
+		return nil
+	}
+
+	var errs []error
+	for _, list := range s.scopeLists(ctx, owner, mailbox.OrgID) {
+		if removeMember(list, mailbox.ID) {
+			if err := s.store.SaveDistributionList(ctx, list); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliverTo 在单个成员邮箱中创建邮件
+func (s *DistributionListService) deliverTo(ctx context.Context, mailbox *domain.Mailbox, input CreateMessageInput) (*domain.Message, error) {
+	if mailbox.Suspended {
+		return nil, ErrMailboxSuspended
+	}
+	if ResolveDomain(ctx, s.store, mailbox.Domain).Lapsed() {
+		return nil, ErrMailboxFrozen
+	}
+	if err := checkMessageQuota(ctx, s.store, s.quotaNotifier, mailbox); err != nil {
+		return nil, err
+	}
+
+	input.MailboxID = mailbox.ID
+	// 附件引用按成员复制，内容 blob 由存储层共享
+	attachments := make([]*domain.Attachment, 0, len(input.Attachments))
+	for _, att := range input.Attachments {
+		copied := *att
+		attachments = append(attachments, &copied)
+	}
+	input.Attachments = attachments
+	return s.messages.Create(ctx, input)
+}
+
+// checkAddress 检查列表地址：格式有效、位于列表所有者控制的已验证域名、未被占用
+func (s *DistributionListService) checkAddress(ctx context.Context, list *domain.DistributionList) error {
+	at := strings.LastIndex(list.Address, "@")
+	if at <= 0 || at == len(list.Address)-1 {
+		return ErrListAddressInvalid
+	}
+	if err := domain.NewEmailValidator().ValidateLocalPart(list.Address[:at]); err != nil {
+		return ErrListAddressInvalid
+	}
+
+	res := ResolveDomain(ctx, s.store, list.Address[at+1:])
+	if res.Kind != DomainKindUser || !sameScope(res.UserDomain.UserID, res.UserDomain.OrgID, list) {
+		return ErrListDomainNotOwned
+	}
+	switch res.State {
+	case DomainStateInactive:
+		return ErrDomainNotVerified
+	case DomainStateGrace, DomainStateLapsed:
+		return ErrDomainExpired
+	}
+
+	if _, err := s.store.GetMailboxByAddress(ctx, list.Address); err == nil {
+		return ErrListAddressTaken
+	}
+	if s.aliases != nil {
+		if _, err := s.aliases.GetAliasByAddress(ctx, list.Address); err == nil {
+			return ErrListAddressTaken
+		}
+	}
+	return nil
+}
+
+// validateMembers 校验成员：数量上限、归属范围一致、不形成环；返回去重后的有序成员
+func (s *DistributionListService) validateMembers(ctx context.Context, list *domain.DistributionList, members []string) ([]string, error) {
+	result := make([]string, 0, len(members))
+	seen := make(map[string]bool, len(members))
+	for _, id := range members {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	if len(result) > s.maxMembers {
+		return nil, ErrListTooLarge
+	}
+
+	for _, id := range result {
+		if id == list.ID {
+			return nil, ErrListLoop
+		}
+		if mailbox, err := s.store.GetMailbox(ctx, id); err == nil {
+			owner := ""
+			if mailbox.UserID != nil {
+				owner = *mailbox.UserID
+			}
+			if !sameScope(owner, mailbox.OrgID, list) {
+				return nil, ErrListMemberInvalid
+			}
+			continue
+		}
+		nested, err := s.store.GetDistributionList(ctx, id)
+		if err != nil || !sameScope(nested.UserID, nested.OrgID, list) {
+			return nil, ErrListMemberInvalid
+		}
+		if s.reaches(ctx, nested, list.ID, map[string]bool{}) {
+			return nil, ErrListLoop
+		}
+	}
+	return result, nil
+}
+
+// reaches 判断从 from 出发的嵌套列表是否包含 target
+func (s *DistributionListService) reaches(ctx context.Context, from *domain.DistributionList, target string, visited map[string]bool) bool {
+	if visited[from.ID] {
+		return false
+	}
+	visited[from.ID] = true
+	for _, id := range from.Members {
+		if id == target {
+			return true
+		}
+		if nested, err := s.store.GetDistributionList(ctx, id); err == nil && s.reaches(ctx, nested, target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// save 保存分发列表，地址冲突映射为业务错误
+func (s *DistributionListService) save(ctx context.Context, list *domain.DistributionList) error {
+	if err := s.store.SaveDistributionList(ctx, list); err != nil {
+		if errors.Is(err, storage.ErrDistributionListExists) {
+			return ErrListAddressTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// scopeLists 列出与给定所有者/组织同一范围的分发列表
+func (s *DistributionListService) scopeLists(ctx context.Context, ownerID string, orgID *string) []*domain.DistributionList {
+	if domain.InOrg(orgID) {
+		lists, _ := s.store.ListDistributionListsByOrgID(ctx, *orgID)
+		return lists
+	}
+	owned, _ := s.store.ListDistributionListsByUserID(ctx, ownerID)
+	lists := make([]*domain.DistributionList, 0, len(owned))
+	for _, list := range owned {
+		if !domain.InOrg(list.OrgID) {
+			lists = append(lists, list)
+		}
+	}
+	return lists
+}
+
+// pruneMember 投递时发现成员已不存在，移出列表
+func (s *DistributionListService) pruneMember(ctx context.Context, list *domain.DistributionList, mailboxID string) {
+	current, err := s.store.GetDistributionList(ctx, list.ID)
+	if err != nil {
+		return
+	}
+	if removeMember(current, mailboxID) {
+		_ = s.store.SaveDistributionList(ctx, current)
+	}
+}
+
+// sameScope 判断资源与列表是否属于同一范围（同一组织，或同一用户的个人资源）
+func sameScope(ownerID string, orgID *string, list *domain.DistributionList) bool {
+	if domain.InOrg(list.OrgID) {
+		return domain.InOrg(orgID) && *orgID == *list.OrgID
+	}
+	return !domain.InOrg(orgID) && ownerID != "" && ownerID == list.UserID
+}
+
+// removeMember 从列表成员中移除 id，返回是否有变化
+func removeMember(list *domain.DistributionList, id string) bool {
+	kept := make([]string, 0, len(list.Members))
+	for _, member := range list.Members {
+		if member != id {
+			kept = append(kept, member)
+		}
+	}
+	if len(kept) == len(list.Members) {
+		return false
+	}
+	list.Members = kept
+	return true
+}
+
+func normalizeListAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
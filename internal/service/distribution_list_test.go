@@ -0,0 +1,237 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+type listFixture struct {
+	store     *memory.Store
+	lists     *DistributionListService
+	mailboxes *MailboxService
+}
+
+// newListFixture 创建用户 alice、她已验证的域名 team.example 和分发列表服务
+func newListFixture(t *testing.T) *listFixture {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	for _, id := range []string{"alice", "bob"} {
+		require.NoError(t, store.CreateUser(t.Context(), &domain.User{
+			ID: id, Email: id + "@corp.example", Username: id, Tier: domain.TierFree, IsActive: true, CreatedAt: time.Now(),
+		}))
+	}
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{
+		ID: "ud-team", UserID: "alice", Domain: "team.example", Mode: domain.DomainModeExclusive,
+		Status: domain.DomainStatusVerified, IsActive: true, CreatedAt: time.Now(),
+	}))
+
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"corp.example"}}}
+	lists := NewDistributionListService(store, NewMessageService(store), cfg)
+	lists.SetAliasRepository(store)
+	mailboxes := NewMailboxService(store, store, cfg)
+	mailboxes.SetMemberPruner(lists)
+	return &listFixture{store: store, lists: lists, mailboxes: mailboxes}
+}
+
+// mailbox 直接写入一个属于 owner 的邮箱
+func (f *listFixture) mailbox(t *testing.T, owner, prefix string) *domain.Mailbox {
+	t.Helper()
+	mailbox := &domain.Mailbox{
+		ID: "mb-" + prefix, Address: prefix + "@team.example", LocalPart: prefix, Domain: "team.example",
+		Token: "token-" + prefix, UserID: &owner, CreatedAt: time.Now(),
+	}
+	require.NoError(t, f.store.SaveMailbox(t.Context(), mailbox))
+	return mailbox
+}
+
+func listMessage(subject string) CreateMessageInput {
+	return CreateMessageInput{
+		From: "sender@example.org", To: "all@team.example", Subject: subject, Text: "hello", Received: time.Now(),
+		Attachments: []*domain.Attachment{{ID: "att-1", Filename: "a.txt", ContentType: "text/plain", Size: 5}},
+	}
+}
+
+func TestDistributionList_FanOut(t *testing.T) {
+	f := newListFixture(t)
+	a, b, c := f.mailbox(t, "alice", "a"), f.mailbox(t, "alice", "b"), f.mailbox(t, "alice", "c")
+
+	list, err := f.lists.Create(t.Context(), CreateDistributionListInput{
+		UserID: "alice", Name: "All", Address: "All@Team.example", Members: []string{a.ID, b.ID, c.ID, a.ID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "all@team.example", list.Address)
+	assert.Equal(t, []string{a.ID, b.ID, c.ID}, list.Members, "成员去重并保持顺序")
+
+	resolved, ok := f.lists.ResolveAddress(t.Context(), "ALL@team.example")
+	require.True(t, ok)
+
+	report, messages := f.lists.Deliver(t.Context(), resolved, listMessage("fan-out"))
+	assert.Equal(t, []string{a.ID, b.ID, c.ID}, report.Delivered)
+	assert.Empty(t, report.Failures)
+	require.Len(t, messages, 3)
+
+	ids := make(map[string]bool)
+	for i, message := range messages {
+		assert.Equal(t, list.Members[i], message.MailboxID)
+		assert.Equal(t, "fan-out", message.Subject)
+		require.Len(t, message.Attachments, 1)
+		ids[message.ID] = true
+	}
+	assert.Len(t, ids, 3, "每个成员一条独立的邮件记录")
+	assert.NotSame(t, messages[0].Attachments[0], messages[1].Attachments[0])
+
+	t.Run("停用后不再解析", func(t *testing.T) {
+		inactive := false
+		_, err := f.lists.Update(t.Context(), "alice", list.ID, UpdateDistributionListInput{IsActive: &inactive})
+		require.NoError(t, err)
+		_, ok := f.lists.ResolveAddress(t.Context(), "all@team.example")
+		assert.False(t, ok)
+	})
+}
+
+func TestDistributionList_PartialFailure(t *testing.T) {
+	f := newListFixture(t)
+	a, b, c := f.mailbox(t, "alice", "a"), f.mailbox(t, "alice", "b"), f.mailbox(t, "alice", "c")
+	b.TotalCount = domain.DefaultQuotas(domain.TierFree).MaxMessagesPerMailbox
+	require.NoError(t, f.store.SaveMailbox(t.Context(), b))
+
+	list, err := f.lists.Create(t.Context(), CreateDistributionListInput{
+		UserID: "alice", Address: "all@team.example", Members: []string{a.ID, b.ID, c.ID},
+	})
+	require.NoError(t, err)
+
+	report, messages := f.lists.Deliver(t.Context(), list, listMessage("partial"))
+	assert.Equal(t, []string{a.ID, c.ID}, report.Delivered, "配额已满的成员不影响其他成员")
+	require.Len(t, report.Failures, 1)
+	assert.Equal(t, b.ID, report.Failures[0].MailboxID)
+	assert.Equal(t, ErrMailboxFull.Error(), report.Failures[0].Error)
+	assert.Len(t, messages, 2)
+
+	t.Run("投递报告可查询", func(t *testing.T) {
+		deliveries, err := f.lists.Deliveries(t.Context(), "alice", list.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, report.ID, deliveries[0].ID)
+		assert.Equal(t, "partial", deliveries[0].Subject)
+
+		_, err = f.lists.Deliveries(t.Context(), "bob", list.ID, 10)
+		assert.ErrorIs(t, err, ErrListNotFound)
+	})
+
+	t.Run("已删除的成员被移出列表", func(t *testing.T) {
+		require.NoError(t, f.mailboxes.Delete(t.Context(), c.ID))
+		stored, err := f.lists.Get(t.Context(), "alice", list.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{a.ID, b.ID}, stored.Members)
+	})
+}
+
+func TestDistributionList_QuotaInteraction(t *testing.T) {
+	f := newListFixture(t)
+	limit := domain.DefaultQuotas(domain.TierFree).MaxMessagesPerMailbox
+	a, b := f.mailbox(t, "alice", "a"), f.mailbox(t, "alice", "b")
+	a.TotalCount = limit - 2
+	require.NoError(t, f.store.SaveMailbox(t.Context(), a))
+
+	list, err := f.lists.Create(t.Context(), CreateDistributionListInput{
+		UserID: "alice", Address: "all@team.example", Members: []string{a.ID, b.ID},
+	})
+	require.NoError(t, err)
+
+	// 每次投递都计入成员邮箱的配额
+	for i := 0; i < 3; i++ {
+		report, _ := f.lists.Deliver(t.Context(), list, listMessage(fmt.Sprintf("round %d", i)))
+		if i < 2 {
+			assert.Equal(t, []string{a.ID, b.ID}, report.Delivered)
+			continue
+		}
+		assert.Equal(t, []string{b.ID}, report.Delivered)
+		require.Len(t, report.Failures, 1)
+		assert.Equal(t, a.ID, report.Failures[0].MailboxID)
+	}
+
+	stored, err := f.store.GetMailbox(t.Context(), a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, limit, stored.TotalCount)
+	stored, err = f.store.GetMailbox(t.Context(), b.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stored.TotalCount)
+
+	t.Run("企业版不限邮件数量", func(t *testing.T) {
+		user, err := f.store.GetUserByID(t.Context(), "alice")
+		require.NoError(t, err)
+		user.Tier = domain.TierEnterprise
+		require.NoError(t, f.store.UpdateUser(t.Context(), user))
+
+		report, _ := f.lists.Deliver(t.Context(), list, listMessage("unlimited"))
+		assert.Equal(t, []string{a.ID, b.ID}, report.Delivered)
+	})
+}
+
+func TestDistributionList_Validation(t *testing.T) {
+	f := newListFixture(t)
+	a := f.mailbox(t, "alice", "alpha")
+	foreign := f.mailbox(t, "bob", "bobs")
+
+	inner, err := f.lists.Create(t.Context(), CreateDistributionListInput{UserID: "alice", Address: "inner@team.example", Members: []string{a.ID}})
+	require.NoError(t, err)
+	outer, err := f.lists.Create(t.Context(), CreateDistributionListInput{UserID: "alice", Address: "outer@team.example", Members: []string{inner.ID}})
+	require.NoError(t, err)
+
+	t.Run("嵌套列表递归展开", func(t *testing.T) {
+		assert.Equal(t, []string{a.ID}, f.lists.Expand(t.Context(), outer))
+	})
+
+	t.Run("保存时拒绝形成环", func(t *testing.T) {
+		_, err := f.lists.Update(t.Context(), "alice", inner.ID, UpdateDistributionListInput{Members: []string{a.ID, outer.ID}})
+		assert.ErrorIs(t, err, ErrListLoop)
+		_, err = f.lists.Update(t.Context(), "alice", inner.ID, UpdateDistributionListInput{Members: []string{inner.ID}})
+		assert.ErrorIs(t, err, ErrListLoop)
+
+		stored, err := f.lists.Get(t.Context(), "alice", inner.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{a.ID}, stored.Members, "被拒绝的更新不落库")
+	})
+
+	t.Run("成员必须属于同一所有者", func(t *testing.T) {
+		_, err := f.lists.Create(t.Context(), CreateDistributionListInput{UserID: "alice", Address: "mixed@team.example", Members: []string{foreign.ID}})
+		assert.ErrorIs(t, err, ErrListMemberInvalid)
+		_, err = f.lists.Create(t.Context(), CreateDistributionListInput{UserID: "alice", Address: "ghost@team.example", Members: []string{"missing"}})
+		assert.ErrorIs(t, err, ErrListMemberInvalid)
+	})
+
+	t.Run("成员数量上限", func(t *testing.T) {
+		members := make([]string, DefaultMaxListMembers+1)
+		for i := range members {
+			members[i] = fmt.Sprintf("mb-%d", i)
+		}
+		_, err := f.lists.Create(t.Context(), CreateDistributionListInput{UserID: "alice", Address: "big@team.example", Members: members})
+		assert.ErrorIs(t, err, ErrListTooLarge)
+	})
+
+	t.Run("地址校验", func(t *testing.T) {
+		_, err := f.lists.Create(t.Context(), CreateDistributionListInput{UserID: "alice", Address: "alpha@team.example"})
+		assert.ErrorIs(t, err, ErrListAddressTaken, "与邮箱地址冲突")
+		_, err = f.lists.Create(t.Context(), CreateDistributionListInput{UserID: "alice", Address: "inner@team.example"})
+		assert.ErrorIs(t, err, ErrListAddressTaken, "与其他列表冲突")
+		_, err = f.lists.Create(t.Context(), CreateDistributionListInput{UserID: "alice", Address: "list@corp.example"})
+		assert.ErrorIs(t, err, ErrListDomainNotOwned, "系统域名不可用")
+		_, err = f.lists.Create(t.Context(), CreateDistributionListInput{UserID: "bob", Address: "list@team.example"})
+		assert.ErrorIs(t, err, ErrListDomainNotOwned, "他人的域名不可用")
+	})
+
+	t.Run("删除列表时从其他列表中移除", func(t *testing.T) {
+		require.NoError(t, f.lists.Delete(t.Context(), "alice", inner.ID))
+		stored, err := f.lists.Get(t.Context(), "alice", outer.ID)
+		require.NoError(t, err)
+		assert.Empty(t, stored.Members)
+	})
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// UserEventNotifier 用户通道通知（如 WebSocket 推送到用户的所有连接）
+type UserEventNotifier interface {
+	NotifyUser(userID, event string, data interface{})
+}
+
+// UserEventDomainExpiring 用户通道事件：域名已过期，处于宽限期
+const UserEventDomainExpiring = "domain_expiring"
+
+// DomainExpiryNotice 域名过期通知内容
+type DomainExpiryNotice struct {
+	DomainID    string    `json:"domainId"`
+	Domain      string    `json:"domain"`
+	Stage       int       `json:"stage"`       // 第几次通知（1 起，逐级升级）
+	FinalNotice bool      `json:"finalNotice"` // 是否为宽限期结束前的最后一次通知
+	ExpiresAt   time.Time `json:"expiresAt"`
+	GraceEndsAt time.Time `json:"graceEndsAt"` // 宽限期结束后拒收邮件、邮箱只读
+}
+
+// DomainLifecycleService 用户域名过期生命周期
+//
+// 过期后进入宽限期：照常收信但不能新建邮箱，并在过期当天、宽限期中点和结束前一天
+// （默认第 0/7/13 天）通知所有者；宽限期结束后拒收邮件、邮箱只读。续期立即恢复并清空通知状态。
+// 状态判断统一使用 EvaluateUserDomain。通知渠道为用户通道事件和 Webhook（暂无邮件通知）。
+type DomainLifecycleService struct {
+	store    domain.Store
+	webhook  *WebhookService   // 可选
+	notifier UserEventNotifier // 可选
+}
+
+// NewDomainLifecycleService 创建域名生命周期服务
+func NewDomainLifecycleService(store domain.Store) *DomainLifecycleService {
+	return &DomainLifecycleService{store: store}
+}
+
+// SetWebhookService 设置 Webhook 服务（避免循环依赖）
+func (s *DomainLifecycleService) SetWebhookService(webhook *WebhookService) {
+	s.webhook = webhook
+}
+
+// SetUserNotifier 设置用户通道通知
+func (s *DomainLifecycleService) SetUserNotifier(notifier UserEventNotifier) {
+	s.notifier = notifier
+}
+
+// GraceNoticeOffsets 宽限期内的通知时间点（相对过期时间）：当天、中点、结束前一天
+func GraceNoticeOffsets(grace time.Duration) []time.Duration {
+	offsets := []time.Duration{0}
+	if mid := grace / 2; mid > 0 {
+		offsets = append(offsets, mid)
+	}
+	if last := grace - 24*time.Hour; last > grace/2 {
+		offsets = append(offsets, last)
+	}
+	return offsets
+}
+
+// CheckExpirations 检查所有用户域名，发送到期的宽限期通知，返回发送的通知数量
+//
+// 每个时间点只通知一次（记录在 GraceNotices）；错过的时间点合并为最新一级通知。
+// 已续期的域名清空通知状态。
+func (s *DomainLifecycleService) CheckExpirations(ctx context.Context) (int, error) {
+	userDomains, err := s.store.ListAllUserDomains(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	policy := CurrentDomainPolicy()
+	now := policy.Now()
+	offsets := GraceNoticeOffsets(policy.GracePeriod)
+
+	sent := 0
+	var errs []error
+	for _, userDomain := range userDomains {
+		switch EvaluateUserDomain(userDomain, now, policy.GracePeriod) {
+		case DomainStateActive:
+			if userDomain.GraceNotices > 0 {
+				userDomain.GraceNotices = 0
+				if err := s.store.SaveUserDomain(ctx, userDomain); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		case DomainStateGrace:
+			due := 0
+			for _, offset := range offsets {
+				if !now.Before(userDomain.ExpiresAt.Add(offset)) {
+					due++
+				}
+			}
+			if due <= userDomain.GraceNotices {
+				continue
+			}
+			userDomain.GraceNotices = due
+			if err := s.store.SaveUserDomain(ctx, userDomain); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			s.notify(userDomain, DomainExpiryNotice{
+				DomainID:    userDomain.ID,
+				Domain:      userDomain.Domain,
+				Stage:       due,
+				FinalNotice: due == len(offsets),
+				ExpiresAt:   *userDomain.ExpiresAt,
+				GraceEndsAt: userDomain.ExpiresAt.Add(policy.GracePeriod),
+			})
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// notify 通过用户通道和 Webhook 通知域名所有者
+func (s *DomainLifecycleService) notify(userDomain *domain.UserDomain, notice DomainExpiryNotice) {
+	if s.notifier != nil {
+		s.notifier.NotifyUser(userDomain.UserID, UserEventDomainExpiring, notice)
+	}
+	if s.webhook != nil {
+		_ = s.webhook.TriggerEvent(context.Background(), userDomain.UserID, domain.WebhookEventDomainExpiring, notice)
+	}
+}
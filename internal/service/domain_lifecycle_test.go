@@ -0,0 +1,199 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+type recordedNotice struct {
+	userID string
+	event  string
+	notice DomainExpiryNotice
+}
+
+// recordingUserNotifier 记录用户通道通知
+type recordingUserNotifier struct {
+	mu      sync.Mutex
+	notices []recordedNotice
+}
+
+func (n *recordingUserNotifier) NotifyUser(userID, event string, data interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notices = append(n.notices, recordedNotice{userID: userID, event: event, notice: data.(DomainExpiryNotice)})
+}
+
+func (n *recordingUserNotifier) stages() []int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	stages := make([]int, 0, len(n.notices))
+	for _, r := range n.notices {
+		stages = append(stages, r.notice.Stage)
+	}
+	return stages
+}
+
+type graceFixture struct {
+	store     *memory.Store
+	domains   *UserDomainService
+	mailboxes *MailboxService
+	lifecycle *DomainLifecycleService
+	notifier  *recordingUserNotifier
+	domain    *domain.UserDomain
+	expiresAt time.Time
+	now       time.Time
+}
+
+// newGraceFixture 创建一个已验证、带邮箱的用户域名，并注入可控时钟
+func newGraceFixture(t *testing.T) *graceFixture {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{
+		ID: "alice", Email: "alice@corp.example", Username: "alice", Tier: domain.TierFree, IsActive: true, CreatedAt: time.Now(),
+	}))
+
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	userDomain := &domain.UserDomain{
+		ID: "ud-1", UserID: "alice", Domain: "owned.example", Mode: domain.DomainModeShared,
+		Status: domain.DomainStatusVerified, IsActive: true, ExpiresAt: &expiresAt, CreatedAt: time.Now(),
+	}
+	require.NoError(t, store.SaveUserDomain(t.Context(), userDomain))
+
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"owned.example"}}}
+	domains := NewUserDomainService(store, cfg)
+	mailboxes := NewMailboxService(store, store, cfg)
+	mailboxes.SetUserDomainService(domains)
+
+	notifier := &recordingUserNotifier{}
+	lifecycle := NewDomainLifecycleService(store)
+	lifecycle.SetUserNotifier(notifier)
+
+	f := &graceFixture{
+		store: store, domains: domains, mailboxes: mailboxes, lifecycle: lifecycle,
+		notifier: notifier, domain: userDomain, expiresAt: expiresAt,
+	}
+	f.at(expiresAt.Add(-time.Hour))
+
+	previous := CurrentDomainPolicy()
+	SetDomainPolicy(DomainPolicy{GracePeriod: 14 * 24 * time.Hour, Now: func() time.Time { return f.now }})
+	t.Cleanup(func() { SetDomainPolicy(previous) })
+	return f
+}
+
+// at 将时钟拨到指定时刻
+func (f *graceFixture) at(now time.Time) {
+	f.now = now
+}
+
+// day 将时钟拨到过期后第 n 天（再加 1 小时，避开边界）
+func (f *graceFixture) day(n int) {
+	f.at(f.expiresAt.Add(time.Duration(n)*24*time.Hour + time.Hour))
+}
+
+func TestDomainGrace_Lifecycle(t *testing.T) {
+	f := newGraceFixture(t)
+	alice := "alice"
+
+	mailbox, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "inbox", Domain: "owned.example", UserID: &alice})
+	require.NoError(t, err)
+
+	t.Run("过期前正常收信和创建", func(t *testing.T) {
+		res := ResolveDomain(t.Context(), f.store, "owned.example")
+		assert.Equal(t, DomainStateActive, res.State)
+		assert.True(t, res.Managed())
+		assert.NoError(t, f.mailboxes.CheckWritable(t.Context(), mailbox))
+	})
+
+	t.Run("宽限期内照常收信但不能新建邮箱", func(t *testing.T) {
+		f.day(3)
+		res := ResolveDomain(t.Context(), f.store, "owned.example")
+		assert.Equal(t, DomainStateGrace, res.State)
+		assert.True(t, res.Managed())
+		assert.False(t, res.Lapsed())
+
+		_, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "another", Domain: "owned.example", UserID: &alice})
+		assert.ErrorIs(t, err, ErrDomainExpired)
+		assert.NoError(t, f.mailboxes.CheckWritable(t.Context(), mailbox), "宽限期内邮箱仍可写")
+	})
+
+	t.Run("宽限期结束后拒收且邮箱只读", func(t *testing.T) {
+		f.day(14)
+		res := ResolveDomain(t.Context(), f.store, "owned.example")
+		assert.Equal(t, DomainStateLapsed, res.State)
+		assert.False(t, res.Managed())
+		assert.True(t, res.Lapsed())
+
+		assert.ErrorIs(t, f.mailboxes.CheckWritable(t.Context(), mailbox), ErrMailboxFrozen)
+		_, err := f.mailboxes.Get(t.Context(), mailbox.ID)
+		assert.NoError(t, err, "冻结的邮箱仍可读取")
+	})
+
+	t.Run("续期后恢复正常", func(t *testing.T) {
+		_, err := f.domains.RenewDomain(t.Context(), f.domain.ID, "bob", f.now.Add(365*24*time.Hour))
+		assert.ErrorIs(t, err, ErrNotDomainOwner)
+		_, err = f.domains.RenewDomain(t.Context(), f.domain.ID, "alice", f.now.Add(-time.Hour))
+		assert.ErrorIs(t, err, ErrInvalidExpiry)
+
+		renewed, err := f.domains.RenewDomain(t.Context(), f.domain.ID, "alice", f.now.Add(365*24*time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, renewed.GraceNotices)
+
+		assert.True(t, ResolveDomain(t.Context(), f.store, "owned.example").Managed())
+		assert.NoError(t, f.mailboxes.CheckWritable(t.Context(), mailbox))
+		_, err = f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "renewed", Domain: "owned.example", UserID: &alice})
+		assert.NoError(t, err)
+	})
+}
+
+func TestDomainGrace_RenewMidGrace(t *testing.T) {
+	f := newGraceFixture(t)
+
+	f.day(8)
+	sent, err := f.lifecycle.CheckExpirations(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	stored, err := f.store.GetUserDomain(t.Context(), f.domain.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.GraceNotices, "错过的通知合并为当前级别")
+
+	// 计费回调续期
+	_, err = f.domains.ExtendDomain(t.Context(), f.domain.ID, f.now.Add(30*24*time.Hour))
+	require.NoError(t, err)
+
+	stored, err = f.store.GetUserDomain(t.Context(), f.domain.ID)
+	require.NoError(t, err)
+	assert.Zero(t, stored.GraceNotices)
+	assert.Equal(t, DomainStateActive, ResolveDomain(t.Context(), f.store, "owned.example").State)
+
+	sent, err = f.lifecycle.CheckExpirations(t.Context())
+	require.NoError(t, err)
+	assert.Zero(t, sent, "续期后不再提醒")
+}
+
+func TestDomainGrace_NotificationSchedule(t *testing.T) {
+	f := newGraceFixture(t)
+
+	assert.Equal(t, []time.Duration{0, 7 * 24 * time.Hour, 13 * 24 * time.Hour}, GraceNoticeOffsets(14*24*time.Hour))
+
+	// 每小时检查一次：第 0、7、13 天各通知一次，不重复
+	for f.at(f.expiresAt.Add(-2 * time.Hour)); f.now.Before(f.expiresAt.Add(16 * 24 * time.Hour)); f.at(f.now.Add(time.Hour)) {
+		_, err := f.lifecycle.CheckExpirations(t.Context())
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, f.notifier.stages())
+	for i, r := range f.notifier.notices {
+		assert.Equal(t, "alice", r.userID)
+		assert.Equal(t, UserEventDomainExpiring, r.event)
+		assert.Equal(t, f.expiresAt.Add(14*24*time.Hour), r.notice.GraceEndsAt)
+		assert.Equal(t, i == 2, r.notice.FinalNotice)
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// DomainKind 域名归属类型
+type DomainKind string
+
+const (
+	DomainKindUnknown DomainKind = ""       // 未被本系统管理
+	DomainKindSystem  DomainKind = "system" // 系统域名
+	DomainKindUser    DomainKind = "user"   // 用户自有域名
+)
+
+// DefaultDomainGracePeriod 用户域名过期后的默认宽限期
+const DefaultDomainGracePeriod = 14 * 24 * time.Hour
+
+// DomainState 用户域名生命周期状态
+type DomainState string
+
+const (
+	DomainStateInactive DomainState = "inactive" // 未验证或已停用
+	DomainStateActive   DomainState = "active"   // 正常
+	DomainStateGrace    DomainState = "grace"    // 已过期，宽限期内：照常收信，禁止新建邮箱
+	DomainStateLapsed   DomainState = "lapsed"   // 宽限期结束：拒收邮件，已有邮箱只读
+)
+
+// DomainPolicy 用户域名过期策略
+type DomainPolicy struct {
+	GracePeriod time.Duration    // 过期后的宽限期
+	Now         func() time.Time // 时钟（测试可注入）
+}
+
+var domainPolicy = DomainPolicy{GracePeriod: DefaultDomainGracePeriod, Now: time.Now}
+
+// SetDomainPolicy 设置用户域名过期策略（启动时调用）
+func SetDomainPolicy(policy DomainPolicy) {
+	if policy.GracePeriod <= 0 {
+		policy.GracePeriod = DefaultDomainGracePeriod
+	}
+	if policy.Now == nil {
+		policy.Now = time.Now
+	}
+	domainPolicy = policy
+}
+
+// CurrentDomainPolicy 获取当前的用户域名过期策略
+func CurrentDomainPolicy() DomainPolicy {
+	return domainPolicy
+}
+
+// EvaluateUserDomain 评估用户域名在 now 时刻的生命周期状态
+//
+// 邮箱创建（CanCreateMailboxOnDomain）、SMTP 收件和邮箱只读冻结都以此为准，规则不会分叉。
+func EvaluateUserDomain(userDomain *domain.UserDomain, now time.Time, grace time.Duration) DomainState {
+	if userDomain == nil || !userDomain.IsActive || userDomain.Status != domain.DomainStatusVerified {
+		return DomainStateInactive
+	}
+	if userDomain.ExpiresAt == nil || now.Before(*userDomain.ExpiresAt) {
+		return DomainStateActive
+	}
+	if now.Before(userDomain.ExpiresAt.Add(grace)) {
+		return DomainStateGrace
+	}
+	return DomainStateLapsed
+}
+
+// DomainResolution 域名解析结果
+type DomainResolution struct {
+	Domain       string
+	Kind         DomainKind
+	Active       bool        // 是否可接收邮件（已验证、已激活，过期的用户域名在宽限期内仍可接收）
+	State        DomainState // 用户域名生命周期状态（系统域名为 active/inactive）
+	SystemDomain *domain.SystemDomain
+	UserDomain   *domain.UserDomain
+}
+
+// Managed 域名是否由本系统管理且可用
+func (r *DomainResolution) Managed() bool {
+	return r != nil && r.Kind != DomainKindUnknown && r.Active
+}
+
+// Lapsed 用户域名是否已过宽限期（拒收邮件、邮箱只读）
+func (r *DomainResolution) Lapsed() bool {
+	return r != nil && r.Kind == DomainKindUser && r.State == DomainStateLapsed
+}
+
+// Sink 域名开启黑洞模式时返回其设置
+//
+// 用户域名只有独享模式且已验证时黑洞模式才生效；切回共享模式后恢复正常收件。
+func (r *DomainResolution) Sink() *domain.SinkSettings {
+	if !r.Managed() {
+		return nil
+	}
+	switch r.Kind {
+	case DomainKindSystem:
+		if r.SystemDomain.Sink.Enabled {
+			return &r.SystemDomain.Sink
+		}
+	case DomainKindUser:
+		if r.UserDomain.Sink.Enabled && r.UserDomain.Mode == domain.DomainModeExclusive {
+			return &r.UserDomain.Sink
+		}
+	}
+	return nil
+}
+
+// CatchAll 用户域名开启通配收件时返回其设置
+//
+// 只有通配模式的用户域名生效，切换到其他模式后恢复为不存在的地址一律拒收。
+func (r *DomainResolution) CatchAll() *domain.CatchAllSettings {
+	if !r.Managed() || r.Kind != DomainKindUser {
+		return nil
+	}
+	if r.UserDomain.CatchAll.Enabled && r.UserDomain.Mode == domain.DomainModeCatchAll {
+		return &r.UserDomain.CatchAll
+	}
+	return nil
+}
+
+// Whitelisted 是否为白名单模式的用户域名（只有白名单中的前缀可以创建邮箱和收信）
+func (r *DomainResolution) Whitelisted() bool {
+	return r != nil && r.Kind == DomainKindUser && r.UserDomain.Mode == domain.DomainModeWhitelist
+}
+
+// ResolveDomain 解析域名归属
+//
+// 邮箱创建、用户域名校验和 SMTP 收件检查共用此函数，避免各处重复查找逻辑。
+// 系统域名优先：同一域名同时存在两条记录时（理论上冲突检查会阻止），以系统域名为准。
+func ResolveDomain(ctx context.Context, store domain.Store, domainName string) *DomainResolution {
+	domainName = strings.TrimSpace(strings.ToLower(domainName))
+	res := &DomainResolution{Domain: domainName}
+	if store == nil || domainName == "" {
+		return res
+	}
+
+	if sysDomain, err := store.GetSystemDomainByDomain(ctx, domainName); err == nil && sysDomain != nil {
+		res.Kind = DomainKindSystem
+		res.SystemDomain = sysDomain
+		res.Active = sysDomain.IsActive && sysDomain.Status == domain.SystemDomainStatusVerified
+		res.State = DomainStateInactive
+		if res.Active {
+			res.State = DomainStateActive
+		}
+		return res
+	}
+
+	if userDomain, err := store.GetUserDomainByDomain(ctx, domainName); err == nil && userDomain != nil {
+		res.Kind = DomainKindUser
+		res.UserDomain = userDomain
+		res.State = EvaluateUserDomain(userDomain, domainPolicy.Now(), domainPolicy.GracePeriod)
+		res.Active = res.State == DomainStateActive || res.State == DomainStateGrace
+		return res
+	}
+
+	return res
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// MaxWhitelistEntries 每个用户域名白名单的条目上限
+const MaxWhitelistEntries = 500
+
+var (
+	ErrWhitelistLocalPartInvalid = errors.New("whitelist local part is invalid")
+	ErrWhitelistEntryExists      = errors.New("local part is already whitelisted")
+	ErrWhitelistEntryNotFound    = errors.New("whitelist entry not found")
+	ErrWhitelistFull             = errors.New("too many whitelist entries")
+	ErrAddressNotWhitelisted     = errors.New("address is not on the domain whitelist")
+)
+
+// DomainWhitelistService 用户域名白名单
+//
+// 白名单模式的域名与共享模式一样任何人都可以创建邮箱，但前缀必须在白名单中（不能使用随机前缀），
+// 发往其他前缀的邮件在 SMTP 收件阶段拒收。条目可以在切换到白名单模式前预先添加。
+type DomainWhitelistService struct {
+	store     domain.Store
+	whitelist storage.DomainWhitelistRepository
+	authz     *Authorizer
+	validator *domain.EmailValidator
+}
+
+// NewDomainWhitelistService 创建域名白名单服务
+func NewDomainWhitelistService(store domain.Store, whitelist storage.DomainWhitelistRepository) *DomainWhitelistService {
+	return &DomainWhitelistService{
+		store:     store,
+		whitelist: whitelist,
+		authz:     NewAuthorizer(store),
+		validator: domain.NewEmailValidator(),
+	}
+}
+
+// List 列出域名的白名单（按前缀排序）
+func (s *DomainWhitelistService) List(ctx context.Context, domainID, userID string) ([]*domain.DomainWhitelistEntry, error) {
+	if _, err := s.ownedDomain(ctx, domainID, userID, ActionRead); err != nil {
+		return nil, err
+	}
+	return s.whitelist.ListDomainWhitelist(ctx, domainID)
+}
+
+// Add 把前缀加入域名白名单（前缀统一转为小写）
+func (s *DomainWhitelistService) Add(ctx context.Context, domainID, userID, localPart string) (*domain.DomainWhitelistEntry, error) {
+	if _, err := s.ownedDomain(ctx, domainID, userID, ActionManage); err != nil {
+		return nil, err
+	}
+	localPart = strings.ToLower(strings.TrimSpace(localPart))
+	if localPart == "" || s.validator.ValidateLocalPart(localPart) != nil {
+		return nil, ErrWhitelistLocalPartInvalid
+	}
+	entries, err := s.whitelist.ListDomainWhitelist(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) >= MaxWhitelistEntries {
+		return nil, ErrWhitelistFull
+	}
+
+	entry := &domain.DomainWhitelistEntry{
+		ID:        uuid.NewString(),
+		DomainID:  domainID,
+		LocalPart: localPart,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.whitelist.AddDomainWhitelistEntry(ctx, entry); err != nil {
+		if errors.Is(err, storage.ErrWhitelistEntryExists) {
+			return nil, ErrWhitelistEntryExists
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Remove 从域名白名单中删除条目（已创建的邮箱保留，但白名单模式下不再收信）
+func (s *DomainWhitelistService) Remove(ctx context.Context, domainID, userID, entryID string) error {
+	if _, err := s.ownedDomain(ctx, domainID, userID, ActionManage); err != nil {
+		return err
+	}
+	if err := s.whitelist.DeleteDomainWhitelistEntry(ctx, domainID, entryID); err != nil {
+		if errors.Is(err, storage.ErrWhitelistEntryNotFound) {
+			return ErrWhitelistEntryNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Allows 收件地址的前缀是否被域名允许（不是白名单模式的域名一律允许）
+func (s *DomainWhitelistService) Allows(ctx context.Context, res *DomainResolution, localPart string) (bool, error) {
+	if !res.Whitelisted() {
+		return true, nil
+	}
+	return s.whitelist.IsLocalPartWhitelisted(ctx, res.UserDomain.ID, strings.ToLower(localPart))
+}
+
+// CheckCreate 检查在域名下以指定前缀创建邮箱是否符合白名单（随机前缀不在白名单中）
+func (s *DomainWhitelistService) CheckCreate(ctx context.Context, domainName, prefix string) error {
+	res := ResolveDomain(ctx, s.store, domainName)
+	if !res.Whitelisted() {
+		return nil
+	}
+	if prefix == "" {
+		return ErrAddressNotWhitelisted
+	}
+	ok, err := s.Allows(ctx, res, prefix)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAddressNotWhitelisted
+	}
+	return nil
+}
+
+// ownedDomain 获取域名并检查用户权限
+func (s *DomainWhitelistService) ownedDomain(ctx context.Context, domainID, userID string, action Action) (*domain.UserDomain, error) {
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
+	if err != nil {
+		return nil, ErrDomainNotFound
+	}
+	if !s.authz.Can(ctx, userID, userDomain.UserID, userDomain.OrgID, action) {
+		return nil, ErrNotDomainOwner
+	}
+	return userDomain, nil
+}
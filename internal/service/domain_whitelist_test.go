@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestDomainWhitelistService(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	owner, stranger := "user-1", "user-2"
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{
+		ID: "ud-1", UserID: owner, Domain: "list.example", Mode: domain.DomainModeWhitelist,
+		Status: domain.DomainStatusVerified, IsActive: true,
+	}))
+	whitelist := NewDomainWhitelistService(store, store)
+
+	cfg := &config.Config{}
+	cfg.Mailbox.AllowedDomains = []string{"list.example"}
+	mailboxes := NewMailboxService(store, store, cfg)
+	mailboxes.SetUserDomainService(NewUserDomainService(store, cfg))
+	mailboxes.SetDomainWhitelist(whitelist)
+
+	t.Run("权限与前缀校验", func(t *testing.T) {
+		_, err := whitelist.Add(t.Context(), "ud-1", stranger, "sales")
+		assert.ErrorIs(t, err, ErrNotDomainOwner)
+		_, err = whitelist.Add(t.Context(), "missing", owner, "sales")
+		assert.ErrorIs(t, err, ErrDomainNotFound)
+		_, err = whitelist.Add(t.Context(), "ud-1", owner, "bad name")
+		assert.ErrorIs(t, err, ErrWhitelistLocalPartInvalid)
+		_, err = whitelist.List(t.Context(), "ud-1", stranger)
+		assert.ErrorIs(t, err, ErrNotDomainOwner)
+	})
+
+	entry, err := whitelist.Add(t.Context(), "ud-1", owner, " Sales ")
+	require.NoError(t, err)
+	assert.Equal(t, "sales", entry.LocalPart)
+
+	t.Run("重复添加", func(t *testing.T) {
+		_, err := whitelist.Add(t.Context(), "ud-1", owner, "SALES")
+		assert.ErrorIs(t, err, ErrWhitelistEntryExists)
+	})
+
+	t.Run("只能以白名单中的前缀创建邮箱", func(t *testing.T) {
+		mailbox, err := mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "sales", Domain: "list.example", UserID: &stranger})
+		require.NoError(t, err)
+		assert.Equal(t, "sales@list.example", mailbox.Address)
+
+		_, err = mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "other", Domain: "list.example"})
+		assert.ErrorIs(t, err, ErrAddressNotWhitelisted)
+		_, err = mailboxes.Create(t.Context(), CreateMailboxInput{Domain: "list.example"})
+		assert.ErrorIs(t, err, ErrAddressNotWhitelisted, "随机前缀不在白名单中")
+	})
+
+	t.Run("删除条目", func(t *testing.T) {
+		assert.ErrorIs(t, whitelist.Remove(t.Context(), "ud-1", owner, "missing"), ErrWhitelistEntryNotFound)
+		require.NoError(t, whitelist.Remove(t.Context(), "ud-1", owner, entry.ID))
+
+		entries, err := whitelist.List(t.Context(), "ud-1", owner)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+		allowed, err := whitelist.Allows(t.Context(), ResolveDomain(t.Context(), store, "list.example"), "sales")
+		require.NoError(t, err)
+		assert.False(t, allowed)
+	})
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/mailfmt"
+	"tempmail/backend/internal/storage"
+)
+
+const (
+	// MaxForwardsPerMailbox 每个邮箱的转发地址上限（含未确认的）
+	MaxForwardsPerMailbox = 5
+	// ForwardCodeTTL 确认码有效期
+	ForwardCodeTTL = 24 * time.Hour
+	// MaxForwardVerifyAttempts 每个确认码允许的尝试次数，用尽后须重新发送
+	MaxForwardVerifyAttempts = 5
+	// forwardLease 投递开始前占用记录的时长，避免重试扫描在投递进行中再次取到同一条
+	forwardLease = 2 * time.Minute
+)
+
+var (
+	ErrForwardRequiresAccount = errors.New("guest mailboxes cannot forward mail")
+	ErrForwardAddressInvalid  = errors.New("forward address is invalid")
+	ErrForwardAddressLocal    = errors.New("cannot forward to an address on a domain served by this instance")
+	ErrForwardLimit           = errors.New("mailbox forward limit reached")
+	ErrForwardNotFound        = errors.New("mailbox forward not found")
+	ErrForwardVerified        = errors.New("forward address is already verified")
+	ErrForwardCodeInvalid     = errors.New("confirmation code is invalid")
+	ErrForwardCodeExpired     = errors.New("confirmation code has expired, request a new one")
+
+	// errForwardSourceGone 邮件或邮箱已删除，放弃投递
+	errForwardSourceGone = errors.New("forwarded message or mailbox no longer exists")
+)
+
+// ForwardOptions 转发限制
+type ForwardOptions struct {
+	AllowGuests bool   // 允许游客邮箱设置转发
+	Domain      string // 确认邮件 Message-ID 的域名部分，也用于生成没有原始内容的邮件
+}
+
+// ForwardingService 邮箱转发到外部地址
+//
+// 目标地址先收到一封带确认码的邮件，邮箱持有人提交确认码后才开始转发。
+// 收信时 SMTP 为每个已确认的地址写入一条投递记录并立即投递，失败后按 Webhook 的退避间隔重试，
+// 记录先落库再投递，实例重启后由重试任务继续。
+type ForwardingService struct {
+	store    domain.Store
+	forwards storage.ForwardRepository
+	messages *MessageService
+	sender   MailSender
+	options  ForwardOptions
+	now      func() time.Time
+}
+
+// NewForwardingService 创建转发服务，sender 为发信中继
+func NewForwardingService(store domain.Store, forwards storage.ForwardRepository, messages *MessageService, sender MailSender, options ForwardOptions) *ForwardingService {
+	return &ForwardingService{
+		store:    store,
+		forwards: forwards,
+		messages: messages,
+		sender:   sender,
+		options:  options,
+		now:      time.Now,
+	}
+}
+
+// List 列出邮箱的转发地址
+func (s *ForwardingService) List(ctx context.Context, mailboxID string) ([]*domain.MailboxForward, error) {
+	forwards, err := s.forwards.ListMailboxForwards(ctx, mailboxID)
+	if err != nil {
+		return nil, err
+	}
+	if forwards == nil {
+		forwards = []*domain.MailboxForward{}
+	}
+	return forwards, nil
+}
+
+// Add 添加转发地址并向其发送确认码
+//
+// 地址已在等待确认时重新生成确认码并重发；已确认时返回 ErrForwardVerified。
+func (s *ForwardingService) Add(ctx context.Context, mailbox *domain.Mailbox, address string) (*domain.MailboxForward, error) {
+	if mailbox.UserID == nil && !s.options.AllowGuests {
+		return nil, ErrForwardRequiresAccount
+	}
+	parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+	if err != nil || !strings.Contains(parsed.Address, "@") {
+		return nil, ErrForwardAddressInvalid
+	}
+	address = strings.ToLower(parsed.Address)
+	_, domainName, _ := strings.Cut(address, "@")
+	// 本实例的域名会再次进入本实例收信，可能形成转发环
+	if ResolveDomain(ctx, s.store, domainName).Kind != DomainKindUnknown {
+		return nil, ErrForwardAddressLocal
+	}
+
+	existing, err := s.forwards.ListMailboxForwards(ctx, mailbox.ID)
+	if err != nil {
+		return nil, err
+	}
+	var forward *domain.MailboxForward
+	for _, item := range existing {
+		if item.Address == address {
+			forward = item
+		}
+	}
+	switch {
+	case forward != nil && forward.Verified():
+		return nil, ErrForwardVerified
+	case forward == nil && len(existing) >= MaxForwardsPerMailbox:
+		return nil, ErrForwardLimit
+	case forward == nil:
+		forward = &domain.MailboxForward{
+			ID:        uuid.New().String(),
+			MailboxID: mailbox.ID,
+			Address:   address,
+			Status:    domain.ForwardStatusPending,
+			CreatedAt: s.now(),
+		}
+	}
+
+	code, err := generateForwardCode()
+	if err != nil {
+		return nil, err
+	}
+	sentAt := s.now()
+	forward.CodeHash = hashForwardCode(code)
+	forward.CodeSentAt = &sentAt
+	forward.VerifyAttempts = 0
+	if err := s.sendConfirmation(ctx, mailbox, forward, code); err != nil {
+		return nil, err
+	}
+	if err := s.forwards.SaveMailboxForward(ctx, forward); err != nil {
+		return nil, err
+	}
+	return forward, nil
+}
+
+// sendConfirmation 向目标地址发送确认码邮件
+func (s *ForwardingService) sendConfirmation(ctx context.Context, mailbox *domain.Mailbox, forward *domain.MailboxForward, code string) error {
+	domainName := s.options.Domain
+	if domainName == "" {
+		domainName = mailbox.Domain
+	}
+	raw := mailfmt.Compose(mailfmt.Outgoing{
+		From:    mailbox.Address,
+		To:      []string{forward.Address},
+		Subject: "Confirm forwarding from " + mailbox.Address,
+		Text: fmt.Sprintf("%s requested that mail received at this temporary mailbox be forwarded to %s.\n\n"+
+			"Confirmation code: %s\n\nThe code expires in %s. If you did not request this, ignore this message and nothing will be forwarded.\n",
+			mailbox.Address, forward.Address, code, ForwardCodeTTL),
+		MessageID: uuid.New().String() + "@" + domainName,
+		Date:      s.now(),
+	})
+	if err := s.sender.Send(ctx, mailbox.Address, []string{forward.Address}, raw); err != nil {
+		return fmt.Errorf("%w: %v", ErrSendRelayFailed, err)
+	}
+	return nil
+}
+
+// Verify 提交确认码确认转发地址
+func (s *ForwardingService) Verify(ctx context.Context, mailboxID, id, code string) (*domain.MailboxForward, error) {
+	forward, err := s.forwards.GetMailboxForward(ctx, mailboxID, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrForwardNotFound) {
+			return nil, ErrForwardNotFound
+		}
+		return nil, err
+	}
+	if forward.Verified() {
+		return nil, ErrForwardVerified
+	}
+	if forward.CodeSentAt == nil || s.now().Sub(*forward.CodeSentAt) > ForwardCodeTTL || forward.VerifyAttempts >= MaxForwardVerifyAttempts {
+		return nil, ErrForwardCodeExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(hashForwardCode(strings.TrimSpace(code))), []byte(forward.CodeHash)) != 1 {
+		forward.VerifyAttempts++
+		if err := s.forwards.SaveMailboxForward(ctx, forward); err != nil {
+			return nil, err
+		}
+		return nil, ErrForwardCodeInvalid
+	}
+
+	verifiedAt := s.now()
+	forward.Status = domain.ForwardStatusVerified
+	forward.VerifiedAt = &verifiedAt
+	forward.CodeHash = ""
+	forward.VerifyAttempts = 0
+	if err := s.forwards.SaveMailboxForward(ctx, forward); err != nil {
+		return nil, err
+	}
+	return forward, nil
+}
+
+// Remove 删除转发地址（未完成的投递一并取消）
+func (s *ForwardingService) Remove(ctx context.Context, mailboxID, id string) error {
+	err := s.forwards.DeleteMailboxForward(ctx, mailboxID, id)
+	if errors.Is(err, storage.ErrForwardNotFound) {
+		return ErrForwardNotFound
+	}
+	return err
+}
+
+// Enqueue 为新入库的邮件写入转发投递记录并立即异步投递（隔离区邮件不转发）
+//
+// 写入失败只影响转发，不影响收信结果。
+func (s *ForwardingService) Enqueue(ctx context.Context, messages []*domain.Message) {
+	forwardsByMailbox := make(map[string][]*domain.MailboxForward)
+	for _, message := range messages {
+		if message.Quarantined {
+			continue
+		}
+		forwards, ok := forwardsByMailbox[message.MailboxID]
+		if !ok {
+			forwards, _ = s.forwards.ListMailboxForwards(ctx, message.MailboxID)
+			forwardsByMailbox[message.MailboxID] = forwards
+		}
+		for _, forward := range forwards {
+			if !forward.Verified() {
+				continue
+			}
+			now := s.now()
+			lease := now.Add(forwardLease)
+			delivery := &domain.ForwardDelivery{
+				ID:        uuid.New().String(),
+				ForwardID: forward.ID,
+				MailboxID: message.MailboxID,
+				MessageID: message.ID,
+				Address:   forward.Address,
+				NextRetry: &lease,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if err := s.forwards.SaveForwardDelivery(ctx, delivery); err != nil {
+				continue
+			}
+			// 邮件已经入库，投递不随 SMTP 会话取消
+			go s.deliver(context.Background(), delivery)
+		}
+	}
+}
+
+// RetryPending 重新投递到期的失败记录
+func (s *ForwardingService) RetryPending(ctx context.Context) error {
+	deliveries, err := s.forwards.ListPendingForwardDeliveries(ctx, s.now(), 20)
+	if err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		lease := s.now().Add(forwardLease)
+		delivery.NextRetry = &lease
+		if err := s.forwards.SaveForwardDelivery(ctx, delivery); err != nil {
+			continue
+		}
+		go s.deliver(context.Background(), delivery)
+	}
+	return nil
+}
+
+// deliver 投递一次并保存结果：成功或邮件已删除时结束，否则按退避安排下次重试（用尽后放弃）
+func (s *ForwardingService) deliver(ctx context.Context, delivery *domain.ForwardDelivery) {
+	delivery.Attempts++
+	err := s.send(ctx, delivery)
+	switch {
+	case err == nil:
+		delivery.Success = true
+		delivery.Error = ""
+		delivery.NextRetry = nil
+	case errors.Is(err, errForwardSourceGone):
+		delivery.Error = err.Error()
+		delivery.NextRetry = nil
+	default:
+		delivery.Error = err.Error()
+		delivery.NextRetry = nil // 重试次数用尽后放弃
+		if index := delivery.Attempts - 1; index < len(retryIntervals) {
+			next := s.now().Add(retryIntervals[index])
+			delivery.NextRetry = &next
+		}
+	}
+	delivery.UpdatedAt = s.now()
+	_ = s.forwards.SaveForwardDelivery(ctx, delivery)
+}
+
+// send 经中继发出转发的邮件：原始内容前加 Resent-* 头，信封发件人为收件邮箱
+func (s *ForwardingService) send(ctx context.Context, delivery *domain.ForwardDelivery) error {
+	message, err := s.messages.Get(ctx, delivery.MailboxID, delivery.MessageID)
+	if err != nil {
+		return errForwardSourceGone
+	}
+	mailbox, err := s.store.GetMailbox(ctx, delivery.MailboxID)
+	if err != nil {
+		return errForwardSourceGone
+	}
+	content := mailfmt.Render(message, s.options.Domain)
+	resent := fmt.Sprintf("Resent-From: %s\r\nResent-To: %s\r\nResent-Date: %s\r\n",
+		mailbox.Address, delivery.Address, s.now().Format(time.RFC1123Z))
+	return s.sender.Send(ctx, mailbox.Address, []string{delivery.Address}, append([]byte(resent), content...))
+}
+
+// generateForwardCode 生成 8 位数字确认码
+func generateForwardCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(100_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%08d", n.Int64()), nil
+}
+
+// hashForwardCode 确认码只保存哈希
+func hashForwardCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
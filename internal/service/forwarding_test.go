@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+)
+
+// lockedMailSender 可并发调用的 fakeMailSender（转发在 goroutine 中投递）
+type lockedMailSender struct {
+	mu sync.Mutex
+	fakeMailSender
+}
+
+func (l *lockedMailSender) Send(ctx context.Context, from string, to []string, message []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fakeMailSender.Send(ctx, from, to, message)
+}
+
+func (l *lockedMailSender) setErr(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.err = err
+}
+
+func (l *lockedMailSender) sentMails() []sentMail {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]sentMail(nil), l.sent...)
+}
+
+var forwardCodePattern = regexp.MustCompile(`Confirmation code: (\d{8})`)
+
+func TestForwardingService(t *testing.T) {
+	setup := func(t *testing.T) (*sendFixture, *ForwardingService, *lockedMailSender) {
+		f := newSendFixture(t, SendOptions{})
+		sender := &lockedMailSender{}
+		forwarding := NewForwardingService(f.store, f.store, f.messages, sender, ForwardOptions{Domain: "mx.temp.example"})
+		return f, forwarding, sender
+	}
+	// addVerified 添加并用邮件中的确认码确认转发地址
+	addVerified := func(t *testing.T, f *sendFixture, forwarding *ForwardingService, sender *lockedMailSender, address string) *domain.MailboxForward {
+		forward, err := forwarding.Add(t.Context(), f.mailbox, address)
+		require.NoError(t, err)
+		mails := sender.sentMails()
+		match := forwardCodePattern.FindStringSubmatch(mails[len(mails)-1].message)
+		require.NotNil(t, match)
+		forward, err = forwarding.Verify(t.Context(), f.mailbox.ID, forward.ID, match[1])
+		require.NoError(t, err)
+		return forward
+	}
+	receive := func(t *testing.T, f *sendFixture) *domain.Message {
+		message, err := f.messages.Create(t.Context(), CreateMessageInput{
+			MailboxID: f.mailbox.ID, From: "shop@example.com", Subject: "Your code",
+			Raw: "From: shop@example.com\r\nSubject: Your code\r\n\r\n123456\r\n",
+		})
+		require.NoError(t, err)
+		return message
+	}
+
+	t.Run("确认码发往目标地址，确认前不转发", func(t *testing.T) {
+		f, forwarding, sender := setup(t)
+		forward, err := forwarding.Add(t.Context(), f.mailbox, "Me <Me@Example.org>")
+		require.NoError(t, err)
+		assert.Equal(t, "me@example.org", forward.Address)
+		assert.Equal(t, domain.ForwardStatusPending, forward.Status)
+
+		mails := sender.sentMails()
+		require.Len(t, mails, 1)
+		assert.Equal(t, []string{"me@example.org"}, mails[0].to)
+		require.NotNil(t, forwardCodePattern.FindStringSubmatch(mails[0].message))
+
+		forwarding.Enqueue(t.Context(), []*domain.Message{receive(t, f)})
+		time.Sleep(20 * time.Millisecond)
+		assert.Len(t, sender.sentMails(), 1)
+
+		_, err = forwarding.Verify(t.Context(), f.mailbox.ID, forward.ID, "00000000")
+		assert.ErrorIs(t, err, ErrForwardCodeInvalid)
+	})
+
+	t.Run("尝试次数用尽后须重新获取确认码", func(t *testing.T) {
+		f, forwarding, sender := setup(t)
+		forward, err := forwarding.Add(t.Context(), f.mailbox, "me@example.org")
+		require.NoError(t, err)
+		code := forwardCodePattern.FindStringSubmatch(sender.sentMails()[0].message)[1]
+		for range MaxForwardVerifyAttempts {
+			_, err = forwarding.Verify(t.Context(), f.mailbox.ID, forward.ID, "wrong")
+			assert.ErrorIs(t, err, ErrForwardCodeInvalid)
+		}
+		_, err = forwarding.Verify(t.Context(), f.mailbox.ID, forward.ID, code)
+		assert.ErrorIs(t, err, ErrForwardCodeExpired)
+
+		// 重新添加同一地址重发确认码
+		again, err := forwarding.Add(t.Context(), f.mailbox, "me@example.org")
+		require.NoError(t, err)
+		assert.Equal(t, forward.ID, again.ID)
+		code = forwardCodePattern.FindStringSubmatch(sender.sentMails()[1].message)[1]
+		verified, err := forwarding.Verify(t.Context(), f.mailbox.ID, forward.ID, code)
+		require.NoError(t, err)
+		assert.True(t, verified.Verified())
+
+		_, err = forwarding.Add(t.Context(), f.mailbox, "me@example.org")
+		assert.ErrorIs(t, err, ErrForwardVerified)
+	})
+
+	t.Run("已确认的地址收到带 Resent 头的原始邮件", func(t *testing.T) {
+		f, forwarding, sender := setup(t)
+		addVerified(t, f, forwarding, sender, "me@example.org")
+		message := receive(t, f)
+
+		forwarding.Enqueue(t.Context(), []*domain.Message{message})
+		require.Eventually(t, func() bool { return len(sender.sentMails()) == 2 }, time.Second, 5*time.Millisecond)
+		forwarded := sender.sentMails()[1]
+		assert.Equal(t, f.mailbox.Address, forwarded.from)
+		assert.Equal(t, []string{"me@example.org"}, forwarded.to)
+		assert.Contains(t, forwarded.message, "Resent-To: me@example.org\r\n")
+		assert.Contains(t, forwarded.message, "Subject: Your code\r\n\r\n123456\r\n")
+	})
+
+	t.Run("投递失败按退避重试", func(t *testing.T) {
+		f, forwarding, sender := setup(t)
+		now := time.Now()
+		var offset atomic.Int64 // 投递在 goroutine 中读取时钟
+		forwarding.now = func() time.Time { return now.Add(time.Duration(offset.Load())) }
+		addVerified(t, f, forwarding, sender, "me@example.org")
+		sender.setErr(errors.New("relay down"))
+
+		forwarding.Enqueue(t.Context(), []*domain.Message{receive(t, f)})
+		var pending []*domain.ForwardDelivery
+		require.Eventually(t, func() bool {
+			pending, _ = f.store.ListPendingForwardDeliveries(t.Context(), now.Add(time.Minute), 10)
+			return len(pending) == 1 && pending[0].Attempts == 1
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, "relay down", pending[0].Error)
+		assert.Equal(t, now.Add(time.Minute), *pending[0].NextRetry)
+
+		sender.setErr(nil)
+		offset.Store(int64(time.Minute))
+		require.NoError(t, forwarding.RetryPending(t.Context()))
+		require.Eventually(t, func() bool { return len(sender.sentMails()) == 2 }, time.Second, 5*time.Millisecond)
+		require.Eventually(t, func() bool {
+			pending, _ = f.store.ListPendingForwardDeliveries(t.Context(), now.Add(time.Hour), 10)
+			return len(pending) == 0
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("游客邮箱、本站域名和数量上限", func(t *testing.T) {
+		f, forwarding, _ := setup(t)
+		guest := f.createMailbox(t, "guest", "")
+		_, err := forwarding.Add(t.Context(), guest, "me@example.org")
+		assert.ErrorIs(t, err, ErrForwardRequiresAccount)
+
+		require.NoError(t, f.store.SaveSystemDomain(t.Context(), &domain.SystemDomain{
+			ID: "sys-1", Domain: "mail.example", Status: domain.SystemDomainStatusVerified, IsActive: true,
+		}))
+		_, err = forwarding.Add(t.Context(), f.mailbox, "other@mail.example")
+		assert.ErrorIs(t, err, ErrForwardAddressLocal)
+		_, err = forwarding.Add(t.Context(), f.mailbox, "not an address")
+		assert.ErrorIs(t, err, ErrForwardAddressInvalid)
+
+		for i := range MaxForwardsPerMailbox {
+			_, err := forwarding.Add(t.Context(), f.mailbox, string(rune('a'+i))+"@example.org")
+			require.NoError(t, err)
+		}
+		_, err = forwarding.Add(t.Context(), f.mailbox, "z@example.org")
+		assert.ErrorIs(t, err, ErrForwardLimit)
+	})
+}
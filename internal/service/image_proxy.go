@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/security"
+)
+
+// ImageProxyPath 图片代理的路由（安全渲染的 HTML 中远程图片改写为此地址）
+const ImageProxyPath = "/v1/image-proxy"
+
+var (
+	ErrImageProxySignature = errors.New("invalid image proxy signature")
+	ErrImageProxyFetch     = errors.New("failed to fetch remote image")
+	ErrImageProxyType      = errors.New("remote resource is not a supported image")
+	ErrImageProxyTooLarge  = errors.New("remote image is too large")
+)
+
+// proxyImageTypes 允许代理的图片类型（SVG 可以携带脚本，不代理）
+var proxyImageTypes = map[string]bool{
+	"image/png": true, "image/jpeg": true, "image/gif": true, "image/webp": true,
+	"image/avif": true, "image/bmp": true, "image/x-icon": true, "image/vnd.microsoft.icon": true,
+}
+
+// ImageProxy 邮件远程图片代理
+//
+// 收件人查看邮件时由服务端加载远程图片，发件方看不到收件人的 IP 和浏览器信息。
+// 代理地址带 HMAC 签名，只能加载安全渲染时改写过的地址，不能当作开放代理使用；
+// 只连接公网地址（包括跳转后的地址），只返回常见光栅图片格式。
+type ImageProxy struct {
+	key     []byte
+	client  *http.Client
+	maxSize int64
+}
+
+// NewImageProxy 创建图片代理，签名密钥由 secret 派生
+func NewImageProxy(secret string, cfg config.RenderConfig) *ImageProxy {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("image-proxy"))
+	return &ImageProxy{
+		key:     mac.Sum(nil),
+		client:  security.NewPublicHTTPClient(cfg.ProxyTimeout),
+		maxSize: cfg.ProxyMaxSize,
+	}
+}
+
+// URL 返回远程图片的代理地址（协议相对地址按 https 处理）
+func (p *ImageProxy) URL(remote string) string {
+	remote = strings.TrimSpace(remote)
+	if strings.HasPrefix(remote, "//") {
+		remote = "https:" + remote
+	}
+	return ImageProxyPath + "?url=" + url.QueryEscape(remote) + "&sig=" + p.sign(remote)
+}
+
+// ProxiedImage 代理加载的图片
+type ProxiedImage struct {
+	ContentType string
+	Data        []byte
+}
+
+// Fetch 校验签名后加载远程图片
+func (p *ImageProxy) Fetch(ctx context.Context, remote, sig string) (*ProxiedImage, error) {
+	if !hmac.Equal([]byte(sig), []byte(p.sign(remote))) {
+		return nil, ErrImageProxySignature
+	}
+	if err := security.ValidatePublicURL(remote); err != nil {
+		return nil, ErrImageProxyFetch
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, remote, nil)
+	if err != nil {
+		return nil, ErrImageProxyFetch
+	}
+	req.Header.Set("Accept", "image/*")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, ErrImageProxyFetch
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrImageProxyFetch
+	}
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !proxyImageTypes[contentType] {
+		return nil, ErrImageProxyType
+	}
+	if resp.ContentLength > p.maxSize {
+		return nil, ErrImageProxyTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, p.maxSize+1))
+	if err != nil {
+		return nil, ErrImageProxyFetch
+	}
+	if int64(len(data)) > p.maxSize {
+		return nil, ErrImageProxyTooLarge
+	}
+	return &ProxiedImage{ContentType: contentType, Data: data}, nil
+}
+
+func (p *ImageProxy) sign(remote string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(remote))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+)
+
+// stubTransport 按地址返回固定响应
+type stubTransport map[string]*http.Response
+
+func (s stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, ok := s[req.URL.String()]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Header: http.Header{}}, nil
+	}
+	return resp, nil
+}
+
+func stubImage(contentType, body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestImageProxy(t *testing.T) {
+	proxy := NewImageProxy("secret-for-tests-0123456789abcdef", config.RenderConfig{ProxyTimeout: time.Second, ProxyMaxSize: 8})
+	proxy.client = &http.Client{Transport: stubTransport{
+		"https://cdn.example/logo.png":  stubImage("image/png", "PNGDATA"),
+		"https://cdn.example/big.png":   stubImage("image/png", "0123456789"),
+		"https://cdn.example/image.svg": stubImage("image/svg+xml", "<svg/>"),
+	}}
+
+	fetch := func(proxied string) (*ProxiedImage, error) {
+		u, err := url.Parse(proxied)
+		require.NoError(t, err)
+		require.Equal(t, ImageProxyPath, u.Path)
+		return proxy.Fetch(t.Context(), u.Query().Get("url"), u.Query().Get("sig"))
+	}
+
+	t.Run("加载签名地址", func(t *testing.T) {
+		image, err := fetch(proxy.URL("//cdn.example/logo.png"))
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", image.ContentType)
+		assert.Equal(t, "PNGDATA", string(image.Data))
+	})
+
+	t.Run("签名不匹配", func(t *testing.T) {
+		_, err := proxy.Fetch(t.Context(), "https://cdn.example/logo.png", "forged")
+		assert.ErrorIs(t, err, ErrImageProxySignature)
+		other := NewImageProxy("another-secret-0123456789abcdefgh", config.RenderConfig{ProxyTimeout: time.Second, ProxyMaxSize: 8})
+		_, err = fetch(other.URL("https://cdn.example/logo.png"))
+		assert.ErrorIs(t, err, ErrImageProxySignature)
+	})
+
+	t.Run("拒绝内网地址、非图片和超大图片", func(t *testing.T) {
+		_, err := fetch(proxy.URL("http://127.0.0.1/logo.png"))
+		assert.ErrorIs(t, err, ErrImageProxyFetch)
+		_, err = fetch(proxy.URL("https://cdn.example/image.svg"))
+		assert.ErrorIs(t, err, ErrImageProxyType)
+		_, err = fetch(proxy.URL("https://cdn.example/big.png"))
+		assert.ErrorIs(t, err, ErrImageProxyTooLarge)
+		_, err = fetch(proxy.URL("https://cdn.example/missing.png"))
+		assert.ErrorIs(t, err, ErrImageProxyFetch)
+	})
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// jwtSecretBytes 轮换生成的密钥长度（base64 编码后 43 个字符）
+const jwtSecretBytes = 32
+
+// JWTKeyStatus 签名密钥状态（不含密钥本身）
+type JWTKeyStatus struct {
+	CurrentKeyID     string     `json:"currentKeyId"`
+	PreviousKeyID    string     `json:"previousKeyId,omitempty"`
+	PreviousRetireAt *time.Time `json:"previousRetireAt,omitempty"` // 旧密钥停止验证的时间
+	RotatedAt        *time.Time `json:"rotatedAt,omitempty"`
+}
+
+// JWTKeyService JWT 签名密钥轮换
+//
+// 轮换后新密钥写入系统配置，其他实例通过运行时配置轮询同步，无需重启；
+// 旧密钥签发的令牌在刷新令牌有效期内仍可验证，之后自动失效。
+type JWTKeyService struct {
+	store   storage.SystemConfigRepository
+	manager *jwtpkg.Manager
+
+	mu        sync.Mutex
+	rotatedAt time.Time // 最近一次应用的轮换时间
+}
+
+// NewJWTKeyService 创建签名密钥轮换服务
+func NewJWTKeyService(store storage.SystemConfigRepository, manager *jwtpkg.Manager) *JWTKeyService {
+	return &JWTKeyService{store: store, manager: manager}
+}
+
+// Load 启动时应用系统配置中已轮换的密钥（覆盖启动配置中的密钥）
+func (s *JWTKeyService) Load(ctx context.Context) error {
+	config, err := s.store.GetSystemConfig(ctx)
+	if err != nil {
+		return err
+	}
+	return s.apply(config)
+}
+
+// Apply 同步系统配置中的密钥（运行时配置刷新回调）
+func (s *JWTKeyService) Apply(config *domain.SystemConfig) {
+	_ = s.apply(config)
+}
+
+func (s *JWTKeyService) apply(config *domain.SystemConfig) error {
+	ring := config.JWTKeys
+	if ring == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ring.RotatedAt.Equal(s.rotatedAt) {
+		return nil
+	}
+
+	current := jwtpkg.Key{ID: ring.Current.ID, Secret: ring.Current.Secret}
+	var previous *jwtpkg.Key
+	if ring.Previous != nil {
+		previous = &jwtpkg.Key{ID: ring.Previous.ID, Secret: ring.Previous.Secret}
+		if ring.Previous.RetireAt != nil {
+			previous.RetireAt = *ring.Previous.RetireAt
+		}
+	}
+	if err := s.manager.SetKeys(current, previous); err != nil {
+		return err
+	}
+	s.rotatedAt = ring.RotatedAt
+	return nil
+}
+
+// Rotate 生成新密钥：当前密钥转为旧密钥，新令牌立即使用新密钥签名
+func (s *JWTKeyService) Rotate(ctx context.Context, actorID string) (*JWTKeyStatus, error) {
+	secret := make([]byte, jwtSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	config, err := s.store.GetSystemConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldCurrent, oldPrevious := s.manager.Keys()
+	if err := s.manager.Rotate(jwtpkg.Key{Secret: base64.RawURLEncoding.EncodeToString(secret)}); err != nil {
+		return nil, err
+	}
+	current, previous := s.manager.Keys()
+
+	ring := &domain.JWTKeyRing{
+		Current:   domain.JWTKey{ID: current.ID, Secret: current.Secret},
+		RotatedAt: time.Now().UTC(),
+		RotatedBy: actorID,
+	}
+	if previous != nil {
+		retireAt := previous.RetireAt
+		ring.Previous = &domain.JWTKey{ID: previous.ID, Secret: previous.Secret, RetireAt: &retireAt}
+	}
+	config.JWTKeys = ring
+	if err := s.store.SaveSystemConfig(ctx, config); err != nil {
+		// 未能持久化时回滚，避免本实例与其他实例使用不同的密钥
+		_ = s.manager.SetKeys(oldCurrent, oldPrevious)
+		return nil, err
+	}
+	s.rotatedAt = ring.RotatedAt
+
+	return s.status(ring.RotatedAt), nil
+}
+
+// Status 当前签名密钥状态
+func (s *JWTKeyService) Status() *JWTKeyStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status(s.rotatedAt)
+}
+
+func (s *JWTKeyService) status(rotatedAt time.Time) *JWTKeyStatus {
+	current, previous := s.manager.Keys()
+	status := &JWTKeyStatus{CurrentKeyID: current.ID}
+	if previous != nil {
+		status.PreviousKeyID = previous.ID
+		if !previous.RetireAt.IsZero() {
+			retireAt := previous.RetireAt
+			status.PreviousRetireAt = &retireAt
+		}
+	}
+	if !rotatedAt.IsZero() {
+		status.RotatedAt = &rotatedAt
+	}
+	return status
+}
@@ -1,10 +1,15 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,8 +20,23 @@ import (
 )
 
 var (
-	ErrDomainNotAllowed = errors.New("domain not allowed")
-	ErrPrefixInvalid    = errors.New("prefix invalid")
+	ErrDomainNotAllowed      = errors.New("domain not allowed")
+	ErrPrefixInvalid         = errors.New("prefix invalid")
+	ErrMailboxFrozen         = errors.New("mailbox is read-only because its domain has expired")
+	ErrPublicInboxNotAllowed = errors.New("public inboxes are not allowed on this domain")
+	ErrInvalidMailboxSort    = errors.New("invalid mailbox sort")
+	ErrMailboxSuspended      = errors.New("mailbox suspended")
+	ErrAddressTaken          = storage.ErrAddressTaken
+)
+
+// randomAddressAttempts 随机生成地址时遇到冲突的最多尝试次数
+const randomAddressAttempts = 5
+
+// 邮箱摘要列表的排序方式
+const (
+	MailboxSortLastActivity = "lastActivity" // 最近活动时间倒序（默认）
+	MailboxSortCreatedAt    = "createdAt"    // 创建时间倒序
+	MailboxSortAddress      = "address"      // 地址字母序
 )
 
 // MailboxService 封装邮箱相关业务操作。
@@ -24,30 +44,85 @@ type MailboxService struct {
 	repo              storage.MailboxRepository
 	store             domain.Store
 	cfg               *config.Config
-	domainSet         map[string]struct{}
+	settings          atomic.Pointer[mailboxSettings] // 允许域名和默认有效期（系统配置修改后替换）
+	randomMu          sync.Mutex                      // rand.Rand 不能并发使用
 	random            *rand.Rand
+	newLocalPart      func() string // 随机前缀来源（测试可替换）
 	tokenAlphabet     []rune
-	userDomainService *UserDomainService     // 用于检查用户域名权限
-	emailValidator    *domain.EmailValidator // 邮箱验证器
+	userDomainService *UserDomainService      // 用于检查用户域名权限
+	whitelist         *DomainWhitelistService // 白名单模式域名的前缀检查（可选）
+	reserved          *ReservedPrefixService  // 系统域名保留前缀检查（可选）
+	emailValidator    *domain.EmailValidator  // 邮箱验证器
+
+	contentStore     MailboxContentStore     // 邮箱内容存储（可选）
+	deletionNotifier MailboxDeletionNotifier // 删除通知（可选）
+	publicRevoker    PublicAccessRevoker     // 取消公开时撤销匿名订阅（可选）
+	suspender        MailboxSuspender        // 停用时断开实时订阅（可选）
+	memberPruner     MailboxMemberPruner     // 从分发列表中移除（可选）
+	expiryNotifier   MailboxExpiryNotifier   // 过期通知（可选）
+	createdNotifier  MailboxCreatedNotifier  // 创建通知（可选）
+	quotaNotifier    QuotaExceededNotifier   // 邮箱数量、邮件数量配额用尽通知（可选）
+	pending          pendingCleanups         // 待对账的尽力清理
 }
 
 // NewMailboxService 创建邮箱业务服务。
 func NewMailboxService(repo storage.MailboxRepository, store domain.Store, cfg *config.Config) *MailboxService {
-	domainSet := make(map[string]struct{}, len(cfg.Mailbox.AllowedDomains))
-	for _, d := range cfg.Mailbox.AllowedDomains {
-		domainSet[d] = struct{}{}
-	}
-
-	return &MailboxService{
-		repo:      repo,
-		store:     store,
-		cfg:       cfg,
-		domainSet: domainSet,
-		random:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	s := &MailboxService{
+		repo:   repo,
+		store:  store,
+		cfg:    cfg,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
 		tokenAlphabet: []rune("abcdefghijklmnopqrstuvwxyz" +
 			"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"),
 		emailValidator: domain.NewEmailValidator(),
 	}
+	s.newLocalPart = s.generateRandomLocalPart
+	s.settings.Store(newMailboxSettings(cfg.Mailbox.AllowedDomains, cfg.Mailbox.DefaultTTL))
+	return s
+}
+
+// mailboxSettings 可在运行时修改的邮箱设置（快照整体替换，读取时无需加锁）
+type mailboxSettings struct {
+	allowedDomains []string
+	domainSet      map[string]struct{}
+	defaultTTL     time.Duration
+}
+
+func newMailboxSettings(allowedDomains []string, defaultTTL time.Duration) *mailboxSettings {
+	domainSet := make(map[string]struct{}, len(allowedDomains))
+	for _, d := range allowedDomains {
+		domainSet[strings.ToLower(d)] = struct{}{}
+	}
+	return &mailboxSettings{allowedDomains: allowedDomains, domainSet: domainSet, defaultTTL: defaultTTL}
+}
+
+// ApplySystemConfig 应用系统配置中的允许域名和默认有效期（注册为 ConfigService 的运行时回调）
+//
+// 域名列表为空或有效期格式无效时保留当前值。
+func (s *MailboxService) ApplySystemConfig(config *domain.SystemConfig) {
+	current := s.settings.Load()
+	domains := current.allowedDomains
+	if len(config.Mailbox.AllowedDomains) > 0 {
+		domains = config.Mailbox.AllowedDomains
+	}
+	ttl := current.defaultTTL
+	if d, err := time.ParseDuration(config.Mailbox.DefaultTTL); err == nil && d >= 0 {
+		ttl = d
+	}
+	if ttl == current.defaultTTL && slices.Equal(domains, current.allowedDomains) {
+		return
+	}
+	s.settings.Store(newMailboxSettings(slices.Clone(domains), ttl))
+}
+
+// AllowedDomains 当前允许创建邮箱的系统域名
+func (s *MailboxService) AllowedDomains() []string {
+	return s.settings.Load().allowedDomains
+}
+
+// DefaultTTL 未指定过期时间的邮箱的默认有效期（0 表示不限）
+func (s *MailboxService) DefaultTTL() time.Duration {
+	return s.settings.Load().defaultTTL
 }
 
 // SetUserDomainService 设置用户域名服务（避免循环依赖）
@@ -55,17 +130,56 @@ func (s *MailboxService) SetUserDomainService(service *UserDomainService) {
 	s.userDomainService = service
 }
 
+// SetDomainWhitelist 设置域名白名单服务（白名单模式的域名只允许白名单中的前缀）
+func (s *MailboxService) SetDomainWhitelist(whitelist *DomainWhitelistService) {
+	s.whitelist = whitelist
+}
+
+// SetReservedPrefixes 设置保留前缀服务（系统域名上的保留前缀和他人的靓号不能创建）
+func (s *MailboxService) SetReservedPrefixes(reserved *ReservedPrefixService) {
+	s.reserved = reserved
+}
+
+// MailboxCreatedNotifier 邮箱创建通知（由使用情况统计实现）
+type MailboxCreatedNotifier interface {
+	NotifyMailboxCreated(mailbox *domain.Mailbox)
+}
+
+// SetCreatedNotifier 设置邮箱创建通知
+func (s *MailboxService) SetCreatedNotifier(notifier MailboxCreatedNotifier) {
+	s.createdNotifier = notifier
+}
+
+// SetQuotaNotifier 设置邮箱数量、邮件数量配额用尽通知
+func (s *MailboxService) SetQuotaNotifier(notifier QuotaExceededNotifier) {
+	s.quotaNotifier = notifier
+}
+
 // CreateMailboxInput 定义创建邮箱所需的输入。
 type CreateMailboxInput struct {
 	Prefix    string
 	Domain    string
 	IPSource  string
 	UserID    *string // 可选：关联的用户ID
+	OrgID     *string // 可选：创建为组织邮箱（需要组织成员身份，受组织配额限制）
 	ExpiresAt *time.Time
+	Public    bool // 创建为公开收件箱（仅限开启 allowPublicInboxes 的系统域名）
+	AutoRenew bool // 开启自动续期（仅限用户邮箱）
 }
 
 // Create 创建新的临时邮箱。
-func (s *MailboxService) Create(input CreateMailboxInput) (*domain.Mailbox, error) {
+func (s *MailboxService) Create(ctx context.Context, input CreateMailboxInput) (*domain.Mailbox, error) {
+	if input.AutoRenew && input.UserID == nil {
+		return nil, ErrRenewRequiresAccount
+	}
+	if domain.InOrg(input.OrgID) {
+		if err := s.checkOrgCreate(ctx, input); err != nil {
+			return nil, err
+		}
+	} else if err := s.checkUserCreate(ctx, input.UserID, 1); err != nil {
+		return nil, err
+	}
+
 	selectedDomain := s.pickDomain(input.Domain)
 	if selectedDomain == "" {
 		return nil, ErrDomainNotAllowed
@@ -73,7 +187,7 @@ func (s *MailboxService) Create(input CreateMailboxInput) (*domain.Mailbox, erro
 
 	// 检查用户域名权限（独享模式检查）
 	if s.userDomainService != nil {
-		canCreate, err := s.userDomainService.CanCreateMailboxOnDomain(selectedDomain, input.UserID)
+		canCreate, err := s.userDomainService.CanCreateMailboxOnDomain(ctx, selectedDomain, input.UserID)
 		if err != nil {
 			return nil, err
 		}
@@ -81,7 +195,62 @@ func (s *MailboxService) Create(input CreateMailboxInput) (*domain.Mailbox, erro
 			return nil, errors.New("no permission to create mailbox on this domain")
 		}
 	}
+	if s.whitelist != nil {
+		if err := s.whitelist.CheckCreate(ctx, selectedDomain, input.Prefix); err != nil {
+			return nil, err
+		}
+	}
+	if s.reserved != nil {
+		if err := s.reserved.CheckCreate(ctx, selectedDomain, input.Prefix, input.UserID); err != nil {
+			return nil, err
+		}
+	}
 
+	if input.Public && !s.allowsPublicInboxes(ctx, selectedDomain) {
+		return nil, ErrPublicInboxNotAllowed
+	}
+
+	return s.create(ctx, input, selectedDomain)
+}
+
+// create 在已选定（并已检查权限）的域名下创建邮箱，更新域名邮箱计数并发出创建通知。
+func (s *MailboxService) create(ctx context.Context, input CreateMailboxInput, selectedDomain string) (*domain.Mailbox, error) {
+	// 未指定前缀时随机生成，地址冲突则换一个前缀重试；指定前缀时冲突直接返回 ErrAddressTaken
+	attempts := 1
+	if input.Prefix == "" {
+		attempts = randomAddressAttempts
+	}
+	var mailbox *domain.Mailbox
+	for attempt := 1; ; attempt++ {
+		var err error
+		mailbox, err = s.buildMailbox(input, selectedDomain)
+		if err != nil {
+			return nil, err
+		}
+		err = s.repo.CreateMailbox(ctx, mailbox)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrAddressTaken) || attempt >= attempts {
+			return nil, err
+		}
+	}
+
+	// 增加所属域名（用户域名或系统域名）的邮箱计数
+	if s.store != nil {
+		s.store.IncrementMailboxCount(ctx, selectedDomain)
+		s.store.IncrementSystemDomainMailboxCount(ctx, selectedDomain)
+	}
+
+	if s.createdNotifier != nil {
+		s.createdNotifier.NotifyMailboxCreated(mailbox)
+	}
+
+	return mailbox, nil
+}
+
+// buildMailbox 生成前缀、ID 和令牌，构造待创建的邮箱。
+func (s *MailboxService) buildMailbox(input CreateMailboxInput, selectedDomain string) (*domain.Mailbox, error) {
 	localPart, err := s.resolveLocalPart(input.Prefix)
 	if err != nil {
 		return nil, err
@@ -110,78 +279,213 @@ func (s *MailboxService) Create(input CreateMailboxInput) (*domain.Mailbox, erro
 		Domain:    selectedDomain,
 		Token:     token,
 		UserID:    input.UserID, // 关联用户ID（游客模式为nil）
+		OrgID:     input.OrgID,
 		CreatedAt: now,
 		IPSource:  input.IPSource,
+		IsPublic:  input.Public,
+		AutoRenew: input.AutoRenew,
 	}
 
 	if input.ExpiresAt != nil {
 		mailbox.ExpiresAt = input.ExpiresAt
 	}
 
-	if err := s.repo.SaveMailbox(mailbox); err != nil {
-		return nil, err
-	}
-
-	// 增加用户域名的邮箱计数
-	if s.store != nil {
-		s.store.IncrementMailboxCount(selectedDomain)
-	}
-
 	return mailbox, nil
 }
 
 // Get 根据 ID 获取邮箱。
-func (s *MailboxService) Get(id string) (*domain.Mailbox, error) {
-	return s.repo.GetMailbox(id)
+func (s *MailboxService) Get(ctx context.Context, id string) (*domain.Mailbox, error) {
+	return s.repo.GetMailbox(ctx, id)
 }
 
 // List 返回全部邮箱快照。
-func (s *MailboxService) List() []domain.Mailbox {
-	return s.repo.ListMailboxes()
+func (s *MailboxService) List(ctx context.Context) []domain.Mailbox {
+	return s.repo.ListMailboxes(ctx)
 }
 
-// ListByUserID 返回指定用户的全部邮箱。
-func (s *MailboxService) ListByUserID(userID string) []domain.Mailbox {
-	return s.repo.ListMailboxesByUserID(userID)
+// ListByUserID 返回指定用户可访问的全部邮箱（个人邮箱及所在组织的邮箱）。
+func (s *MailboxService) ListByUserID(ctx context.Context, userID string) []domain.Mailbox {
+	owned := s.repo.ListMailboxesByUserID(ctx, userID)
+	if s.store == nil {
+		return owned
+	}
+
+	seen := make(map[string]struct{}, len(owned))
+	result := make([]domain.Mailbox, 0, len(owned))
+	for _, mb := range owned {
+		if domain.InOrg(mb.OrgID) {
+			continue // 组织邮箱按当前成员关系列出
+		}
+		seen[mb.ID] = struct{}{}
+		result = append(result, mb)
+	}
+	for _, orgID := range OrgIDsForUser(ctx, s.store, userID) {
+		for _, mb := range s.repo.ListMailboxesByOrgID(ctx, orgID) {
+			if _, ok := seen[mb.ID]; ok {
+				continue
+			}
+			seen[mb.ID] = struct{}{}
+			result = append(result, mb)
+		}
+	}
+	return result
 }
 
-// Delete 删除指定邮箱。
-func (s *MailboxService) Delete(id string) error {
-	// 先获取邮箱信息（用于减少计数）
-	mailbox, err := s.repo.GetMailbox(id)
+// ListSummariesByUserID 返回用户可访问邮箱的摘要（实时数量和最近邮件预览），按 sortBy 排序。
+// 个人邮箱一次查询取出，组织邮箱每个组织一次查询。
+func (s *MailboxService) ListSummariesByUserID(ctx context.Context, userID, sortBy string) ([]domain.MailboxSummary, error) {
+	less, ok := mailboxSummaryOrders[sortBy]
+	if !ok {
+		return nil, ErrInvalidMailboxSort
+	}
+
+	owned, err := s.repo.ListMailboxSummariesByUserID(ctx, userID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	result := make([]domain.MailboxSummary, 0, len(owned))
+	seen := make(map[string]struct{}, len(owned))
+	for _, summary := range owned {
+		if s.store != nil && domain.InOrg(summary.OrgID) {
+			continue // 组织邮箱按当前成员关系列出
+		}
+		seen[summary.ID] = struct{}{}
+		result = append(result, summary)
+	}
+	if s.store != nil {
+		for _, orgID := range OrgIDsForUser(ctx, s.store, userID) {
+			summaries, err := s.repo.ListMailboxSummariesByOrgID(ctx, orgID)
+			if err != nil {
+				return nil, err
+			}
+			for _, summary := range summaries {
+				if _, ok := seen[summary.ID]; ok {
+					continue
+				}
+				seen[summary.ID] = struct{}{}
+				result = append(result, summary)
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return less(&result[i], &result[j]) })
+	return result, nil
+}
+
+// mailboxSummaryOrders 排序方式到比较函数（空值为默认排序）
+var mailboxSummaryOrders = map[string]func(a, b *domain.MailboxSummary) bool{
+	"":                      lessByLastActivity,
+	MailboxSortLastActivity: lessByLastActivity,
+	MailboxSortCreatedAt: func(a, b *domain.MailboxSummary) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.Address < b.Address
+	},
+	MailboxSortAddress: func(a, b *domain.MailboxSummary) bool {
+		return a.Address < b.Address
+	},
+}
+
+func lessByLastActivity(a, b *domain.MailboxSummary) bool {
+	at, bt := a.LastActivityAt(), b.LastActivityAt()
+	if !at.Equal(bt) {
+		return at.After(bt)
 	}
+	return a.Address < b.Address
+}
 
-	// 删除邮箱
-	if err := s.repo.DeleteMailbox(id); err != nil {
+// checkOrgCreate 检查组织邮箱的创建权限和组织配额
+func (s *MailboxService) checkOrgCreate(ctx context.Context, input CreateMailboxInput) error {
+	if s.store == nil || input.UserID == nil {
+		return ErrNotOrgMember
+	}
+	if _, err := NewAuthorizer(s.store).RequireOrgRole(ctx, *input.UserID, *input.OrgID, domain.OrgRoleMember); err != nil {
 		return err
 	}
+	err := checkOrgMailboxQuota(ctx, s.store, *input.OrgID)
+	if errors.Is(err, ErrOrgQuotaExceeded) && s.quotaNotifier != nil {
+		if org, orgErr := s.store.GetOrganization(ctx, *input.OrgID); orgErr == nil {
+			s.quotaNotifier.NotifyQuotaExceeded(QuotaExceededData{
+				Scope: QuotaScopeOrgMailboxes,
+				Limit: domain.DefaultQuotas(org.Tier).MaxMailboxes,
+				OrgID: org.ID,
+			})
+		}
+	}
+	return err
+}
 
-	// 减少用户域名的邮箱计数
-	if s.store != nil && mailbox != nil {
-		s.store.DecrementMailboxCount(mailbox.Domain)
+// checkUserCreate 检查用户个人邮箱数量配额（游客邮箱不受限）
+func (s *MailboxService) checkUserCreate(ctx context.Context, userID *string, n int) error {
+	if s.store == nil || userID == nil {
+		return nil
+	}
+	err := checkUserMailboxQuota(ctx, s.store, *userID, n)
+	var quota *QuotaError
+	if errors.As(err, &quota) && s.quotaNotifier != nil {
+		s.quotaNotifier.NotifyQuotaExceeded(QuotaExceededData{
+			Scope:  QuotaScopeUserMailboxes,
+			Limit:  quota.Limit,
+			UserID: *userID,
+		})
 	}
+	return err
+}
 
+// CheckWritable 检查邮箱是否可写
+//
+// 所属用户域名过了宽限期后，邮箱冻结为只读：已有邮件仍可读取，直到邮箱自身过期。
+func (s *MailboxService) CheckWritable(ctx context.Context, mailbox *domain.Mailbox) error {
+	if s.store == nil || mailbox == nil {
+		return nil
+	}
+	if ResolveDomain(ctx, s.store, mailbox.Domain).Lapsed() {
+		return ErrMailboxFrozen
+	}
 	return nil
 }
 
 // GetByAddress 根据邮箱地址获取邮箱。
-func (s *MailboxService) GetByAddress(address string) (*domain.Mailbox, error) {
+func (s *MailboxService) GetByAddress(ctx context.Context, address string) (*domain.Mailbox, error) {
 	address = strings.ToLower(strings.TrimSpace(address))
 	if address == "" {
 		return nil, ErrDomainNotAllowed
 	}
-	return s.repo.GetMailboxByAddress(address)
+	return s.repo.GetMailboxByAddress(ctx, address)
+}
+
+// GetByAddresses 批量按地址查询邮箱，返回地址到邮箱的映射（不存在或已过期的地址不在结果中）。
+func (s *MailboxService) GetByAddresses(ctx context.Context, addresses []string) (map[string]*domain.Mailbox, error) {
+	normalized := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if address = strings.ToLower(strings.TrimSpace(address)); address != "" {
+			normalized = append(normalized, address)
+		}
+	}
+	result := make(map[string]*domain.Mailbox, len(normalized))
+	if len(normalized) == 0 {
+		return result, nil
+	}
+
+	mailboxes, err := s.repo.GetMailboxesByAddresses(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+	for i := range mailboxes {
+		result[mailboxes[i].Address] = &mailboxes[i]
+	}
+	return result, nil
 }
 
 // pickDomain 挑选合法的邮箱域名。
 func (s *MailboxService) pickDomain(requested string) string {
+	settings := s.settings.Load()
 	if requested == "" {
-		return s.cfg.Mailbox.AllowedDomains[0]
+		return settings.allowedDomains[0]
 	}
 	requested = strings.ToLower(strings.TrimSpace(requested))
-	if _, ok := s.domainSet[requested]; ok {
+	if _, ok := settings.domainSet[requested]; ok {
 		return requested
 	}
 	return ""
@@ -190,7 +494,7 @@ func (s *MailboxService) pickDomain(requested string) string {
 // resolveLocalPart 生成或验证邮箱前缀。
 func (s *MailboxService) resolveLocalPart(prefix string) (string, error) {
 	if prefix == "" {
-		return s.generateRandomLocalPart(), nil
+		return s.newLocalPart(), nil
 	}
 	prefix = strings.ToLower(prefix)
 	// 使用新的验证器验证本地部分
@@ -210,6 +514,8 @@ func (s *MailboxService) generateRandomLocalPart() string {
 
 // generateToken 生成邮箱访问令牌。
 func (s *MailboxService) generateToken(length int) string {
+	s.randomMu.Lock()
+	defer s.randomMu.Unlock()
 	b := make([]rune, length)
 	for i := 0; i < length; i++ {
 		idx := s.random.Intn(len(s.tokenAlphabet))
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// MaxBatchMailboxes 批量创建一次最多创建的邮箱数
+const MaxBatchMailboxes = 500
+
+// BatchRandPlaceholder 前缀模板中的随机部分占位符
+const BatchRandPlaceholder = "{rand}"
+
+// batchRandLength 占位符替换成的随机字符数
+const batchRandLength = 8
+
+var (
+	ErrBatchCountInvalid   = errors.New("invalid batch mailbox count")
+	ErrBatchPatternInvalid = errors.New("invalid batch prefix pattern")
+)
+
+// BatchCreateMailboxInput 批量创建邮箱的参数
+type BatchCreateMailboxInput struct {
+	Count     int
+	Pattern   string // 前缀模板，必须包含一次 {rand}，如 qa-{rand}；为空时使用随机前缀
+	Domain    string // 为空时选一个允许的域名，所有邮箱共用
+	IPSource  string
+	UserID    *string
+	OrgID     *string
+	ExpiresAt *time.Time // 所有邮箱共用的过期时间
+}
+
+// CreateBatch 在同一域名下批量创建随机地址的邮箱，返回的邮箱带访问令牌。
+//
+// 权限和配额按单个创建的规则逐个检查；任一邮箱创建失败时删除本次已创建的邮箱并返回错误。
+func (s *MailboxService) CreateBatch(ctx context.Context, input BatchCreateMailboxInput) ([]*domain.Mailbox, error) {
+	if input.Count < 1 || input.Count > MaxBatchMailboxes {
+		return nil, ErrBatchCountInvalid
+	}
+	if input.Pattern != "" && strings.Count(input.Pattern, BatchRandPlaceholder) != 1 {
+		return nil, ErrBatchPatternInvalid
+	}
+	selectedDomain := s.pickDomain(input.Domain)
+	if selectedDomain == "" {
+		return nil, ErrDomainNotAllowed
+	}
+	// 个人邮箱数量配额按整批检查，避免创建到一半才失败
+	if !domain.InOrg(input.OrgID) {
+		if err := s.checkUserCreate(ctx, input.UserID, input.Count); err != nil {
+			return nil, err
+		}
+	}
+
+	created := make([]*domain.Mailbox, 0, input.Count)
+	for range input.Count {
+		mailbox, err := s.createBatchMailbox(ctx, input, selectedDomain)
+		if err != nil {
+			s.rollbackBatch(ctx, created)
+			return nil, err
+		}
+		created = append(created, mailbox)
+	}
+	return created, nil
+}
+
+// createBatchMailbox 按模板生成前缀创建一个邮箱，地址冲突时换一个随机部分重试
+func (s *MailboxService) createBatchMailbox(ctx context.Context, input BatchCreateMailboxInput, selectedDomain string) (*domain.Mailbox, error) {
+	create := CreateMailboxInput{
+		Domain:    selectedDomain,
+		IPSource:  input.IPSource,
+		UserID:    input.UserID,
+		OrgID:     input.OrgID,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if input.Pattern == "" {
+		return s.Create(ctx, create)
+	}
+	for attempt := 1; ; attempt++ {
+		random := s.newLocalPart()
+		create.Prefix = strings.Replace(input.Pattern, BatchRandPlaceholder, random[:min(len(random), batchRandLength)], 1)
+		mailbox, err := s.Create(ctx, create)
+		if !errors.Is(err, ErrAddressTaken) || attempt >= randomAddressAttempts {
+			return mailbox, err
+		}
+	}
+}
+
+// rollbackBatch 删除批量创建中已创建的邮箱（尽力而为）
+func (s *MailboxService) rollbackBatch(ctx context.Context, created []*domain.Mailbox) {
+	for _, mailbox := range created {
+		_ = s.deleteMailbox(context.WithoutCancel(ctx), mailbox)
+	}
+}
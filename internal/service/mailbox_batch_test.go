@@ -0,0 +1,82 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestMailboxService_CreateBatch(t *testing.T) {
+	newService := func() (*MailboxService, *memory.Store) {
+		store := memory.NewStore(time.Hour)
+		cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.mail", "other.mail"}, DefaultTTL: time.Hour}}
+		return NewMailboxService(store, store, cfg), store
+	}
+	userID := "user-1"
+
+	t.Run("同一域名下按模板创建并共用过期时间", func(t *testing.T) {
+		svc, _ := newService()
+		expiresAt := time.Now().Add(2 * time.Hour).UTC()
+		mailboxes, err := svc.CreateBatch(t.Context(), BatchCreateMailboxInput{
+			Count: 20, Pattern: "QA-{rand}", UserID: &userID, ExpiresAt: &expiresAt,
+		})
+		require.NoError(t, err)
+		require.Len(t, mailboxes, 20)
+
+		seen := make(map[string]bool)
+		for _, mailbox := range mailboxes {
+			assert.Equal(t, mailboxes[0].Domain, mailbox.Domain)
+			assert.Regexp(t, `^qa-[0-9a-f]{8}$`, mailbox.LocalPart)
+			assert.NotEmpty(t, mailbox.Token)
+			assert.Equal(t, expiresAt, *mailbox.ExpiresAt)
+			assert.False(t, seen[mailbox.Address])
+			seen[mailbox.Address] = true
+		}
+	})
+
+	t.Run("随机部分冲突时重试", func(t *testing.T) {
+		svc, _ := newService()
+		calls := 0
+		svc.newLocalPart = func() string {
+			calls++
+			if calls <= 2 {
+				return "aaaaaaaaaaaa"
+			}
+			return strings.Repeat("b", 11) + string(rune('a'+calls))
+		}
+		mailboxes, err := svc.CreateBatch(t.Context(), BatchCreateMailboxInput{Count: 2, Pattern: "ci-{rand}", Domain: "temp.mail", UserID: &userID})
+		require.NoError(t, err)
+		assert.Equal(t, "ci-aaaaaaaa@temp.mail", mailboxes[0].Address)
+		assert.Equal(t, "ci-bbbbbbbb@temp.mail", mailboxes[1].Address)
+	})
+
+	t.Run("失败时删除本次已创建的邮箱", func(t *testing.T) {
+		svc, store := newService()
+		svc.newLocalPart = func() string { return "samesamesame" }
+		_, err := svc.CreateBatch(t.Context(), BatchCreateMailboxInput{Count: 3, Pattern: "x-{rand}", Domain: "temp.mail", UserID: &userID})
+		assert.ErrorIs(t, err, ErrAddressTaken)
+		assert.Empty(t, store.ListMailboxes(t.Context()))
+	})
+
+	t.Run("无效参数", func(t *testing.T) {
+		svc, _ := newService()
+		for _, input := range []BatchCreateMailboxInput{
+			{Count: 0}, {Count: MaxBatchMailboxes + 1},
+		} {
+			_, err := svc.CreateBatch(t.Context(), input)
+			assert.ErrorIs(t, err, ErrBatchCountInvalid)
+		}
+		for _, pattern := range []string{"qa", "{rand}-{rand}"} {
+			_, err := svc.CreateBatch(t.Context(), BatchCreateMailboxInput{Count: 1, Pattern: pattern})
+			assert.ErrorIs(t, err, ErrBatchPatternInvalid)
+		}
+		_, err := svc.CreateBatch(t.Context(), BatchCreateMailboxInput{Count: 1, Domain: "evil.example"})
+		assert.ErrorIs(t, err, ErrDomainNotAllowed)
+	})
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"tempmail/backend/internal/domain"
+)
+
+var (
+	ErrClaimTokenInvalid   = errors.New("mailbox token is invalid")
+	ErrMailboxAlreadyOwned = errors.New("mailbox already belongs to an account")
+	ErrClaimPublicInbox    = errors.New("public inboxes cannot be claimed")
+)
+
+// Claim 将游客邮箱归入用户名下（注册或登录后保留之前创建的邮箱）
+//
+// 须出示邮箱令牌；认领计入用户的个人邮箱配额，有效期超过用户等级最长生存时间（从创建时间算起）的截断到上限。
+// 已属于该用户时原样返回，属于其他用户或组织时返回 ErrMailboxAlreadyOwned。
+func (s *MailboxService) Claim(ctx context.Context, id, token, userID string) (*domain.Mailbox, error) {
+	mailbox, err := s.repo.GetMailbox(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(mailbox.Token)) != 1 {
+		return nil, ErrClaimTokenInvalid
+	}
+	if mailbox.UserID != nil && *mailbox.UserID == userID && !domain.InOrg(mailbox.OrgID) {
+		return mailbox, nil
+	}
+	if mailbox.UserID != nil || domain.InOrg(mailbox.OrgID) {
+		return nil, ErrMailboxAlreadyOwned
+	}
+	if mailbox.Suspended {
+		return nil, ErrMailboxSuspended
+	}
+	// 公开收件箱任何人都能读，归入个人名下会让其他人的邮件落到该用户账户里
+	if mailbox.IsPublic {
+		return nil, ErrClaimPublicInbox
+	}
+	if err := s.checkUserCreate(ctx, &userID, 1); err != nil {
+		return nil, err
+	}
+
+	claimed := *mailbox
+	claimed.UserID = &userID
+	limit, err := s.lifetimeLimit(ctx, &claimed)
+	if err != nil {
+		return nil, err
+	}
+	if limit != nil {
+		expiresAt := claimed.CreatedAt.Add(s.DefaultTTL()) // 未设置过期时间时按默认有效期
+		if claimed.ExpiresAt != nil {
+			expiresAt = *claimed.ExpiresAt
+		}
+		if expiresAt.After(*limit) {
+			claimed.ExpiresAt = limit
+		}
+		if claimed.IdleOriginalExpiresAt != nil && claimed.IdleOriginalExpiresAt.After(*limit) {
+			claimed.IdleOriginalExpiresAt = limit
+		}
+	}
+	if err := s.repo.SaveMailbox(ctx, &claimed); err != nil {
+		return nil, err
+	}
+	return &claimed, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestMailboxService_Claim(t *testing.T) {
+	newFixture := func(t *testing.T) (*MailboxService, *memory.Store) {
+		t.Helper()
+		store := memory.NewStore(time.Hour)
+		require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: "user-1", Email: "u1@example.com", Username: "u1", Tier: domain.TierFree}))
+		require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: "user-2", Email: "u2@example.com", Username: "u2", Tier: domain.TierFree}))
+		cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.mail"}, DefaultTTL: time.Hour}}
+		return NewMailboxService(store, store, cfg), store
+	}
+	createGuest := func(t *testing.T, svc *MailboxService) *domain.Mailbox {
+		t.Helper()
+		mailbox, err := svc.Create(t.Context(), CreateMailboxInput{})
+		require.NoError(t, err)
+		return mailbox
+	}
+
+	t.Run("凭令牌认领后出现在用户的邮箱列表中", func(t *testing.T) {
+		svc, _ := newFixture(t)
+		guest := createGuest(t, svc)
+		assert.Empty(t, svc.ListByUserID(t.Context(), "user-1"))
+
+		_, err := svc.Claim(t.Context(), guest.ID, "wrong-token", "user-1")
+		assert.ErrorIs(t, err, ErrClaimTokenInvalid)
+
+		claimed, err := svc.Claim(t.Context(), guest.ID, guest.Token, "user-1")
+		require.NoError(t, err)
+		require.NotNil(t, claimed.UserID)
+		assert.Equal(t, "user-1", *claimed.UserID)
+		owned := svc.ListByUserID(t.Context(), "user-1")
+		require.Len(t, owned, 1)
+		assert.Equal(t, guest.ID, owned[0].ID)
+
+		// 重复认领幂等，其他用户不能再认领
+		_, err = svc.Claim(t.Context(), guest.ID, guest.Token, "user-1")
+		assert.NoError(t, err)
+		_, err = svc.Claim(t.Context(), guest.ID, guest.Token, "user-2")
+		assert.ErrorIs(t, err, ErrMailboxAlreadyOwned)
+	})
+
+	t.Run("计入个人邮箱配额", func(t *testing.T) {
+		svc, _ := newFixture(t)
+		userID := "user-1"
+		for i := 0; i < domain.DefaultQuotas(domain.TierFree).MaxMailboxes; i++ {
+			_, err := svc.Create(t.Context(), CreateMailboxInput{UserID: &userID})
+			require.NoError(t, err)
+		}
+		guest := createGuest(t, svc)
+
+		_, err := svc.Claim(t.Context(), guest.ID, guest.Token, userID)
+		assert.ErrorIs(t, err, ErrMailboxQuotaExceeded)
+		reloaded, err := svc.Get(t.Context(), guest.ID)
+		require.NoError(t, err)
+		assert.Nil(t, reloaded.UserID, "配额不足时邮箱保持游客身份")
+	})
+
+	t.Run("有效期截断到用户等级的最长生存时间", func(t *testing.T) {
+		svc, _ := newFixture(t)
+		guest := createGuest(t, svc)
+		expiresAt := guest.CreatedAt.Add(72 * time.Hour)
+		guest.ExpiresAt = &expiresAt
+		require.NoError(t, svc.repo.SaveMailbox(t.Context(), guest))
+
+		claimed, err := svc.Claim(t.Context(), guest.ID, guest.Token, "user-1")
+		require.NoError(t, err)
+		require.NotNil(t, claimed.ExpiresAt)
+		assert.WithinDuration(t, guest.CreatedAt.Add(24*time.Hour), *claimed.ExpiresAt, time.Second)
+	})
+
+	t.Run("公开收件箱不能认领", func(t *testing.T) {
+		svc, _ := newFixture(t)
+		guest := createGuest(t, svc)
+		guest.IsPublic = true
+		require.NoError(t, svc.repo.SaveMailbox(t.Context(), guest))
+
+		_, err := svc.Claim(t.Context(), guest.ID, guest.Token, "user-1")
+		assert.ErrorIs(t, err, ErrClaimPublicInbox)
+	})
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// MailboxContentStore 邮箱内容存储（文件系统等），删除邮箱时一并清理
+type MailboxContentStore interface {
+	DeleteMailbox(mailboxID string) error
+	ListMailboxIDs() ([]string, error)
+}
+
+// MailboxDeletionNotifier 邮箱删除通知（由 WebSocket Hub 实现）
+type MailboxDeletionNotifier interface {
+	NotifyMailboxDeleted(mailboxID string)
+}
+
+// MailboxMemberPruner 将已删除的邮箱从引用它的集合（分发列表）中移除
+type MailboxMemberPruner interface {
+	PruneMailbox(ctx context.Context, mailbox *domain.Mailbox) error
+}
+
+// MailboxExpiryNotifier 邮箱过期通知（由 WebhookService 实现，在删除前触发 mailbox.expired）
+type MailboxExpiryNotifier interface {
+	NotifyMailboxExpired(mailbox *domain.Mailbox)
+}
+
+// mailboxCacheEvictor 可清除邮箱缓存的存储（混合存储实现）
+type mailboxCacheEvictor interface {
+	EvictMailboxCache(mailboxID string) error
+}
+
+// pendingCleanups 尽力清理失败、等待对账重试的邮箱
+type pendingCleanups struct {
+	mu  sync.Mutex
+	ids map[string]struct{}
+}
+
+func (p *pendingCleanups) add(mailboxID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ids == nil {
+		p.ids = make(map[string]struct{})
+	}
+	p.ids[mailboxID] = struct{}{}
+}
+
+func (p *pendingCleanups) take() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]string, 0, len(p.ids))
+	for id := range p.ids {
+		ids = append(ids, id)
+	}
+	p.ids = nil
+	return ids
+}
+
+// SetContentStore 设置邮箱内容存储
+func (s *MailboxService) SetContentStore(contentStore MailboxContentStore) {
+	s.contentStore = contentStore
+}
+
+// SetDeletionNotifier 设置邮箱删除通知（避免依赖 websocket 包）
+func (s *MailboxService) SetDeletionNotifier(notifier MailboxDeletionNotifier) {
+	s.deletionNotifier = notifier
+}
+
+// SetMemberPruner 设置分发列表成员清理
+func (s *MailboxService) SetMemberPruner(pruner MailboxMemberPruner) {
+	s.memberPruner = pruner
+}
+
+// SetExpiryNotifier 设置邮箱过期通知
+func (s *MailboxService) SetExpiryNotifier(notifier MailboxExpiryNotifier) {
+	s.expiryNotifier = notifier
+}
+
+// Delete 删除指定邮箱及其所有附属资源。
+//
+// 这是删除邮箱的唯一入口：HTTP 删除、过期清理和用户清除都经过这里。
+// 主存储（邮件、邮件标签、别名、邮箱 Webhook、邮箱本身）在一个事务内删除，失败则整体失败；
+// 之后递减域名计数、取消待重试的 Webhook 投递、通知 WebSocket 客户端，
+// 文件内容和缓存属于尽力清理，失败时记入待对账列表，由 ReconcileOrphans 重试。
+func (s *MailboxService) Delete(ctx context.Context, id string) error {
+	mailbox, err := s.repo.GetMailbox(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.deleteMailbox(ctx, mailbox)
+}
+
+// DeleteExpired 删除所有已过期的邮箱，返回删除数量
+func (s *MailboxService) DeleteExpired(ctx context.Context) (int, error) {
+	expired, err := s.repo.ListExpiredMailboxes(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var errs []error
+	for i := range expired {
+		if s.expiryNotifier != nil {
+			s.expiryNotifier.NotifyMailboxExpired(&expired[i])
+		}
+		if err := s.deleteMailbox(ctx, &expired[i]); err != nil {
+			// 已被其他路径删除的邮箱不算失败
+			if !errors.Is(err, storage.ErrMailboxNotFound) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		count++
+	}
+	return count, errors.Join(errs...)
+}
+
+// DeleteByUserID 删除用户的所有个人邮箱（用户清除时使用，组织邮箱归组织所有，保留）
+func (s *MailboxService) DeleteByUserID(ctx context.Context, userID string) error {
+	var errs []error
+	for _, mb := range s.repo.ListMailboxesByUserID(ctx, userID) {
+		if domain.InOrg(mb.OrgID) {
+			continue
+		}
+		mailbox := mb
+		if err := s.deleteMailbox(ctx, &mailbox); err != nil && !errors.Is(err, storage.ErrMailboxNotFound) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReconcileOrphans 对账清理：重试失败的尽力清理，并删除内容存储中没有对应邮箱的目录。
+// 返回清理的邮箱数量。
+func (s *MailboxService) ReconcileOrphans(ctx context.Context) (int, error) {
+	cleaned := make(map[string]struct{})
+	var errs []error
+
+	for _, id := range s.pending.take() {
+		if err := s.cleanupArtifacts(id); err != nil {
+			s.pending.add(id)
+			errs = append(errs, err)
+			continue
+		}
+		cleaned[id] = struct{}{}
+	}
+
+	if s.contentStore != nil {
+		ids, err := s.contentStore.ListMailboxIDs()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, id := range ids {
+			if _, ok := cleaned[id]; ok {
+				continue
+			}
+			// 只有确认邮箱不存在才清理，数据库故障时不能误删内容
+			if _, err := s.repo.GetMailbox(ctx, id); !errors.Is(err, storage.ErrMailboxNotFound) {
+				continue
+			}
+			if err := s.cleanupArtifacts(id); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			cleaned[id] = struct{}{}
+		}
+	}
+
+	return len(cleaned), errors.Join(errs...)
+}
+
+// deleteMailbox 删除邮箱：主存储删除必须成功，其余为附属清理
+func (s *MailboxService) deleteMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	if err := s.repo.DeleteMailbox(ctx, mailbox.ID); err != nil {
+		return err
+	}
+
+	// 减少所属域名（用户域名或系统域名）的邮箱计数，与创建时对称
+	if s.store != nil {
+		s.store.DecrementMailboxCount(ctx, mailbox.Domain)
+		s.store.DecrementSystemDomainMailboxCount(ctx, mailbox.Domain)
+	}
+
+	// 分发列表成员尽力移除；遗漏的成员在投递时发现邮箱不存在后也会被移除
+	if s.memberPruner != nil {
+		_ = s.memberPruner.PruneMailbox(ctx, mailbox)
+	}
+
+	if s.deletionNotifier != nil {
+		s.deletionNotifier.NotifyMailboxDeleted(mailbox.ID)
+	}
+
+	if err := s.cleanupArtifacts(mailbox.ID); err != nil {
+		s.pending.add(mailbox.ID)
+	}
+	return nil
+}
+
+// cleanupArtifacts 清理邮箱的附属资源（可重复执行）：待重试投递、文件内容、缓存
+func (s *MailboxService) cleanupArtifacts(mailboxID string) error {
+	var errs []error
+	if s.store != nil {
+		if _, err := s.store.CancelPendingDeliveries(context.Background(), mailboxID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if s.contentStore != nil {
+		if err := s.contentStore.DeleteMailbox(mailboxID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if evictor, ok := s.store.(mailboxCacheEvictor); ok {
+		if err := evictor.EvictMailboxCache(mailboxID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
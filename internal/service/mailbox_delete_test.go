@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/filesystem"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// recordingNotifier 记录删除通知
+type recordingNotifier struct {
+	deleted []string
+}
+
+func (n *recordingNotifier) NotifyMailboxDeleted(mailboxID string) {
+	n.deleted = append(n.deleted, mailboxID)
+}
+
+// flakyContentStore 前 failures 次删除失败的内容存储
+type flakyContentStore struct {
+	*filesystem.Store
+	failures int
+}
+
+func (f *flakyContentStore) DeleteMailbox(mailboxID string) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("disk unavailable")
+	}
+	return f.Store.DeleteMailbox(mailboxID)
+}
+
+type deletionFixture struct {
+	store     *memory.Store
+	fs        *filesystem.Store
+	mailboxes *MailboxService
+	messages  *MessageService
+	notifier  *recordingNotifier
+}
+
+func newDeletionFixture(t *testing.T) *deletionFixture {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	fs, err := filesystem.NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"corp.example"}}}
+	mailboxes := NewMailboxService(store, store, cfg)
+	mailboxes.SetContentStore(fs)
+	notifier := &recordingNotifier{}
+	mailboxes.SetDeletionNotifier(notifier)
+
+	messages := NewMessageService(store)
+	messages.SetFilesystemStore(fs)
+
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{ID: "ud-1", UserID: "user-1", Domain: "corp.example"}))
+	return &deletionFixture{store: store, fs: fs, mailboxes: mailboxes, messages: messages, notifier: notifier}
+}
+
+// seedMailbox 创建带邮件、标签、别名和待重试投递的邮箱
+func (f *deletionFixture) seedMailbox(t *testing.T, prefix string) (*domain.Mailbox, *domain.Message) {
+	t.Helper()
+	userID := "user-1"
+	mailbox, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: prefix, Domain: "corp.example", UserID: &userID})
+	require.NoError(t, err)
+
+	msg, err := f.messages.Create(t.Context(), CreateMessageInput{
+		MailboxID: mailbox.ID, From: "a@example.com", Subject: "hi",
+		Raw: "Subject: hi\r\n\r\nbody", Text: "body",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, f.store.CreateTag(t.Context(), &domain.Tag{ID: "tag-" + prefix, UserID: userID, Name: prefix}))
+	require.NoError(t, f.store.AddMessageTag(t.Context(), msg.ID, "tag-"+prefix))
+	require.NoError(t, f.store.SaveAlias(t.Context(), &domain.MailboxAlias{
+		ID: "alias-" + prefix, MailboxID: mailbox.ID, Address: prefix + "-alias@corp.example", IsActive: true,
+	}))
+
+	require.NoError(t, f.store.CreateWebhook(t.Context(), &domain.Webhook{ID: "wh-" + prefix, UserID: userID, IsActive: true}))
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, f.store.RecordDelivery(t.Context(), &domain.WebhookDelivery{
+		ID: "dl-" + prefix, WebhookID: "wh-" + prefix, MailboxID: mailbox.ID, NextRetry: &past,
+	}))
+	return mailbox, msg
+}
+
+func TestMailboxService_DeleteRemovesDependents(t *testing.T) {
+	f := newDeletionFixture(t)
+	mailbox, msg := f.seedMailbox(t, "sales")
+	other, _ := f.seedMailbox(t, "ops")
+
+	userDomain, err := f.store.GetUserDomain(t.Context(), "ud-1")
+	require.NoError(t, err)
+	require.Equal(t, 2, userDomain.MailboxCount)
+
+	require.NoError(t, f.mailboxes.Delete(t.Context(), mailbox.ID))
+
+	t.Run("主存储数据全部删除", func(t *testing.T) {
+		_, err := f.store.GetMailbox(t.Context(), mailbox.ID)
+		assert.Error(t, err)
+		messages, _ := f.store.ListMessages(t.Context(), mailbox.ID)
+		assert.Empty(t, messages)
+		_, err = f.store.GetAlias(t.Context(), "alias-sales")
+		assert.Error(t, err)
+		tags, err := f.store.GetMessageTags(t.Context(), msg.ID)
+		require.NoError(t, err)
+		assert.Empty(t, tags)
+	})
+
+	t.Run("文件内容删除", func(t *testing.T) {
+		ids, err := f.fs.ListMailboxIDs()
+		require.NoError(t, err)
+		assert.Equal(t, []string{other.ID}, ids)
+	})
+
+	t.Run("域名计数递减", func(t *testing.T) {
+		userDomain, err := f.store.GetUserDomain(t.Context(), "ud-1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, userDomain.MailboxCount)
+	})
+
+	t.Run("待重试投递被取消", func(t *testing.T) {
+		pending, err := f.store.GetPendingDeliveries(t.Context(), 10)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, other.ID, pending[0].MailboxID)
+	})
+
+	t.Run("通知在线客户端", func(t *testing.T) {
+		assert.Equal(t, []string{mailbox.ID}, f.notifier.deleted)
+	})
+
+	t.Run("其他邮箱不受影响", func(t *testing.T) {
+		_, err := f.store.GetMailbox(t.Context(), other.ID)
+		assert.NoError(t, err)
+		_, err = f.store.GetAlias(t.Context(), "alias-ops")
+		assert.NoError(t, err)
+	})
+}
+
+func TestMailboxService_ReconcileOrphans(t *testing.T) {
+	t.Run("文件删除失败时留下可对账的孤儿", func(t *testing.T) {
+		f := newDeletionFixture(t)
+		flaky := &flakyContentStore{Store: f.fs, failures: 1}
+		f.mailboxes.SetContentStore(flaky)
+		mailbox, _ := f.seedMailbox(t, "sales")
+
+		// 主存储删除成功，文件清理失败不影响结果
+		require.NoError(t, f.mailboxes.Delete(t.Context(), mailbox.ID))
+		_, err := f.store.GetMailbox(t.Context(), mailbox.ID)
+		assert.Error(t, err)
+		ids, err := f.fs.ListMailboxIDs()
+		require.NoError(t, err)
+		assert.Equal(t, []string{mailbox.ID}, ids)
+
+		cleaned, err := f.mailboxes.ReconcileOrphans(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 1, cleaned)
+		ids, err = f.fs.ListMailboxIDs()
+		require.NoError(t, err)
+		assert.Empty(t, ids)
+	})
+
+	t.Run("扫描清理没有对应邮箱的内容，保留存活邮箱", func(t *testing.T) {
+		f := newDeletionFixture(t)
+		live, _ := f.seedMailbox(t, "live")
+		_, err := f.fs.SaveMessageRaw("ghost-mailbox", "m1", []byte("orphan"))
+		require.NoError(t, err)
+
+		cleaned, err := f.mailboxes.ReconcileOrphans(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 1, cleaned)
+		ids, err := f.fs.ListMailboxIDs()
+		require.NoError(t, err)
+		assert.Equal(t, []string{live.ID}, ids)
+	})
+}
+
+func TestMailboxService_DeleteExpired(t *testing.T) {
+	f := newDeletionFixture(t)
+	mailbox, _ := f.seedMailbox(t, "old")
+
+	// 直接改写过期时间（内存存储返回的是内部指针）
+	stored, err := f.store.GetMailbox(t.Context(), mailbox.ID)
+	require.NoError(t, err)
+	expired := time.Now().Add(-time.Second)
+	stored.ExpiresAt = &expired
+
+	count, err := f.mailboxes.DeleteExpired(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{mailbox.ID}, f.notifier.deleted)
+
+	ids, err := f.fs.ListMailboxIDs()
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+	_, err = f.store.GetAlias(t.Context(), "alias-old")
+	assert.Error(t, err)
+}
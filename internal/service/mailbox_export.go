@@ -0,0 +1,143 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"path"
+	"sort"
+	"strings"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/mailfmt"
+)
+
+// 邮箱导出格式
+const (
+	MailboxExportMbox   = "mbox"    // 单个 mboxrd 文件
+	MailboxExportEMLZip = "eml-zip" // zip 压缩包：每封邮件一个 .eml，附件另存一份
+)
+
+var ErrExportFormatInvalid = errors.New("export format must be mbox or eml-zip")
+
+// MailboxExportService 把邮箱中的邮件导出为归档（如 QA 在邮箱过期前留存验证邮件）
+//
+// 导出内容为原始邮件（文件系统存储中的 .eml）；没有原始内容的邮件由元数据和正文生成，
+// 与 POP3/IMAP 收到的内容一致。邮件逐封读取并流式写出，不在内存中拼装整个归档。
+type MailboxExportService struct {
+	messages   *MessageService
+	serverName string // 生成邮件的 Message-ID 域名部分
+}
+
+// NewMailboxExportService 创建邮箱导出服务
+func NewMailboxExportService(messages *MessageService, serverName string) *MailboxExportService {
+	return &MailboxExportService{messages: messages, serverName: serverName}
+}
+
+// ValidFormat 是否为支持的导出格式
+func (s *MailboxExportService) ValidFormat(format string) bool {
+	return format == MailboxExportMbox || format == MailboxExportEMLZip
+}
+
+// Filename 下载时使用的文件名，如 box@example.com.mbox
+func (s *MailboxExportService) Filename(mailbox *domain.Mailbox, format string) string {
+	if format == MailboxExportEMLZip {
+		return mailbox.Address + ".zip"
+	}
+	return mailbox.Address + ".mbox"
+}
+
+// Export 按接收时间顺序写出邮箱中的全部邮件（含隔离区），ctx 取消后立即停止写入
+func (s *MailboxExportService) Export(ctx context.Context, mailboxID, format string, w io.Writer) error {
+	if !s.ValidFormat(format) {
+		return ErrExportFormatInvalid
+	}
+	list, err := s.messages.repo.ListMessages(ctx, mailboxID)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].ReceivedAt.Before(list[j].ReceivedAt) })
+
+	w = &contextWriter{ctx: ctx, w: w}
+	if format == MailboxExportMbox {
+		for _, item := range list {
+			message, err := s.messages.Get(ctx, mailboxID, item.ID)
+			if err != nil {
+				return err
+			}
+			if err := mailfmt.WriteMbox(w, envelopeSender(message.From), message.ReceivedAt, mailfmt.Render(message, s.serverName)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	archive := zip.NewWriter(w)
+	for i, item := range list {
+		message, err := s.messages.Get(ctx, mailboxID, item.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.writeEML(archive, i+1, message); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// writeEML 写入一封邮件及其附件：0001-<邮件ID>.eml、0001-<邮件ID>/<附件名>
+func (s *MailboxExportService) writeEML(archive *zip.Writer, seq int, message *domain.Message) error {
+	base := fmt.Sprintf("%04d-%s", seq, message.ID)
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: base + ".eml", Method: zip.Deflate, Modified: message.ReceivedAt})
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(mailfmt.Render(message, s.serverName)); err != nil {
+		return err
+	}
+
+	used := make(map[string]bool, len(message.Attachments))
+	for _, att := range message.Attachments {
+		name := exportAttachmentName(att, used)
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: base + "/" + name, Method: zip.Deflate, Modified: message.ReceivedAt})
+		if err != nil {
+			return err
+		}
+		content, err := att.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportAttachmentName 去掉路径部分的附件文件名，同一封邮件中重名时加序号
+func exportAttachmentName(att *domain.Attachment, used map[string]bool) string {
+	name := path.Base(strings.ReplaceAll(att.Filename, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		name = att.ID
+	}
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for n := 2; used[name]; n++ {
+		name = fmt.Sprintf("%s-%d%s", stem, n, ext)
+	}
+	used[name] = true
+	return name
+}
+
+// envelopeSender 从 From 头取出地址作为 mbox 分隔行的发件人
+func envelopeSender(from string) string {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		return addr.Address
+	}
+	return from
+}
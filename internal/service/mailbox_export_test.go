@@ -0,0 +1,77 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestMailboxExportService_Export(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires := time.Now().Add(time.Hour)
+	setup := func(t *testing.T) *MailboxExportService {
+		store := memory.NewStore(24 * time.Hour)
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+			ID: "mb-1", Address: "qa@temp.mail", LocalPart: "qa", Domain: "temp.mail", Token: "token", CreatedAt: now, ExpiresAt: &expires,
+		}))
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+			ID: "msg-2", MailboxID: "mb-1", From: "Shop <shop@example.com>", Subject: "second",
+			Raw: "Subject: second\r\n\r\nFrom the shop\r\n", ReceivedAt: now.Add(time.Minute), CreatedAt: now,
+			Attachments: []*domain.Attachment{
+				{ID: "att-1", Filename: "../invoice.pdf", Content: []byte("pdf-1"), Size: 5},
+				{ID: "att-2", Filename: "invoice.pdf", Content: []byte("pdf-2"), Size: 5},
+			},
+		}))
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+			ID: "msg-1", MailboxID: "mb-1", From: "verify@example.com", Subject: "first",
+			Text: "code 123456", ReceivedAt: now, CreatedAt: now,
+		}))
+		return NewMailboxExportService(NewMessageService(store), "temp.mail")
+	}
+
+	t.Run("mbox 按接收时间排序", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, setup(t).Export(t.Context(), "mb-1", MailboxExportMbox, &buf))
+
+		content := buf.String()
+		assert.True(t, strings.HasPrefix(content, "From verify@example.com Sun Mar  1 12:00:00 2026\n"), content)
+		assert.Contains(t, content, "code 123456")
+		assert.Contains(t, content, "\nFrom shop@example.com Sun Mar  1 12:01:00 2026\nSubject: second\n\n>From the shop\n")
+		assert.Less(t, strings.Index(content, "code 123456"), strings.Index(content, "Subject: second"))
+	})
+
+	t.Run("eml-zip 包含邮件和附件", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, setup(t).Export(t.Context(), "mb-1", MailboxExportEMLZip, &buf))
+
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		files := map[string]string{}
+		for _, f := range archive.File {
+			r, err := f.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			files[f.Name] = string(content)
+		}
+		assert.Len(t, files, 4)
+		assert.Contains(t, files["0001-msg-1.eml"], "code 123456")
+		assert.Equal(t, "Subject: second\r\n\r\nFrom the shop\r\n", files["0002-msg-2.eml"])
+		assert.Equal(t, "pdf-1", files["0002-msg-2/invoice.pdf"])
+		assert.Equal(t, "pdf-2", files["0002-msg-2/invoice-2.pdf"])
+	})
+
+	t.Run("不支持的格式", func(t *testing.T) {
+		err := setup(t).Export(t.Context(), "mb-1", "pst", io.Discard)
+		assert.ErrorIs(t, err, ErrExportFormatInvalid)
+	})
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// UserEventMailboxesIdle 用户通道事件：名下有邮箱长期未被访问
+const UserEventMailboxesIdle = "mailboxes_idle"
+
+// touchInterval 同一邮箱两次写入访问时间的最小间隔
+const touchInterval = time.Hour
+
+// maxTouchEntries 访问时间缓存上限，超出时清理过期条目
+const maxTouchEntries = 100000
+
+// IdleMailbox 闲置通知中的单个邮箱
+type IdleMailbox struct {
+	MailboxID      string     `json:"mailboxId"`
+	Address        string     `json:"address"`
+	LastAccessedAt time.Time  `json:"lastAccessedAt"`      // 从未访问过时为创建时间
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"` // 处理后的过期时间（shorten-ttl 时已缩短）
+}
+
+// IdleMailboxNotice 闲置邮箱通知内容（按所有者合并）
+type IdleMailboxNotice struct {
+	Action    string        `json:"action"`
+	Mailboxes []IdleMailbox `json:"mailboxes"`
+}
+
+// MailboxIdleService 闲置邮箱检测
+//
+// 访问（API 读取邮件、WebSocket 订阅）时记录 LastAccessedAt，进程内合并为每个邮箱每小时最多写一次；
+// SMTP 投递不算访问。定时任务按系统配置的闲置策略找出闲置的用户邮箱，每个邮箱只提醒一次，
+// 按所有者合并后通过用户通道事件和 Webhook 通知；shorten-ttl 时把有效期缩短到 WarnBefore 之后。
+// 再次访问即清除闲置标记并恢复原有效期。游客邮箱不参与。
+type MailboxIdleService struct {
+	store    storage.Store
+	webhook  *WebhookService   // 可选
+	notifier UserEventNotifier // 可选
+	now      func() time.Time
+
+	mu      sync.Mutex
+	touched map[string]time.Time // 邮箱ID -> 最近一次写入访问时间
+}
+
+// NewMailboxIdleService 创建闲置邮箱检测服务
+func NewMailboxIdleService(store storage.Store) *MailboxIdleService {
+	return &MailboxIdleService{store: store, now: time.Now, touched: make(map[string]time.Time)}
+}
+
+// SetWebhookService 设置 Webhook 服务（避免循环依赖）
+func (s *MailboxIdleService) SetWebhookService(webhook *WebhookService) {
+	s.webhook = webhook
+}
+
+// SetUserNotifier 设置用户通道通知
+func (s *MailboxIdleService) SetUserNotifier(notifier UserEventNotifier) {
+	s.notifier = notifier
+}
+
+// Touch 记录邮箱被访问，同一邮箱每小时最多写一次存储
+func (s *MailboxIdleService) Touch(ctx context.Context, mailboxID string) error {
+	now := s.now()
+
+	s.mu.Lock()
+	if last, ok := s.touched[mailboxID]; ok && now.Sub(last) < touchInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	if len(s.touched) >= maxTouchEntries {
+		for id, last := range s.touched {
+			if now.Sub(last) >= touchInterval {
+				delete(s.touched, id)
+			}
+		}
+	}
+	s.touched[mailboxID] = now
+	s.mu.Unlock()
+
+	if err := s.store.TouchMailbox(ctx, mailboxID, now); err != nil {
+		// 写入失败时允许下次访问重试
+		s.mu.Lock()
+		delete(s.touched, mailboxID)
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// CheckIdle 按闲置策略处理闲置邮箱，返回本次标记的邮箱数量
+func (s *MailboxIdleService) CheckIdle(ctx context.Context) (int, error) {
+	config, err := s.store.GetSystemConfig(ctx)
+	if err != nil {
+		return 0, err
+	}
+	policy := config.Idle
+	if !policy.Enabled() {
+		return 0, nil
+	}
+	idleAfter, err := time.ParseDuration(policy.IdleAfter)
+	if err != nil || idleAfter <= 0 {
+		return 0, errors.New("invalid idle policy: idleAfter")
+	}
+	warnBefore, err := time.ParseDuration(policy.WarnBefore)
+	if err != nil || warnBefore <= 0 {
+		return 0, errors.New("invalid idle policy: warnBefore")
+	}
+
+	now := s.now()
+	mailboxes, err := s.store.ListIdleMailboxes(ctx, now.Add(-idleAfter), now)
+	if err != nil {
+		return 0, err
+	}
+
+	byOwner := make(map[string][]IdleMailbox)
+	marked := 0
+	var errs []error
+	for _, mb := range mailboxes {
+		if mb.UserID == nil {
+			continue
+		}
+		var shortenTo *time.Time
+		expiresAt := mb.ExpiresAt
+		if policy.Action == domain.IdleActionShortenTTL {
+			// 只缩短不延长：原有效期更早时保持不变
+			target := now.Add(warnBefore)
+			if mb.ExpiresAt == nil || mb.ExpiresAt.After(target) {
+				shortenTo = &target
+				expiresAt = &target
+			}
+		}
+		if err := s.store.MarkMailboxIdle(ctx, mb.ID, now, shortenTo); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		lastAccessed := mb.CreatedAt
+		if mb.LastAccessedAt != nil {
+			lastAccessed = *mb.LastAccessedAt
+		}
+		byOwner[*mb.UserID] = append(byOwner[*mb.UserID], IdleMailbox{
+			MailboxID:      mb.ID,
+			Address:        mb.Address,
+			LastAccessedAt: lastAccessed,
+			ExpiresAt:      expiresAt,
+		})
+		marked++
+	}
+
+	for userID, idle := range byOwner {
+		sort.Slice(idle, func(i, j int) bool { return idle[i].Address < idle[j].Address })
+		s.notify(ctx, userID, IdleMailboxNotice{Action: policy.Action, Mailboxes: idle})
+	}
+	return marked, errors.Join(errs...)
+}
+
+// notify 通过用户通道和 Webhook 通知邮箱所有者
+func (s *MailboxIdleService) notify(ctx context.Context, userID string, notice IdleMailboxNotice) {
+	if s.notifier != nil {
+		s.notifier.NotifyUser(userID, UserEventMailboxesIdle, notice)
+	}
+	if s.webhook != nil {
+		_ = s.webhook.TriggerEvent(ctx, userID, domain.WebhookEventMailboxIdle, notice)
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// countingTouchStore 统计访问时间的写入次数
+type countingTouchStore struct {
+	*memory.Store
+	mu      sync.Mutex
+	touches int
+}
+
+func (s *countingTouchStore) TouchMailbox(ctx context.Context, mailboxID string, at time.Time) error {
+	s.mu.Lock()
+	s.touches++
+	s.mu.Unlock()
+	return s.Store.TouchMailbox(ctx, mailboxID, at)
+}
+
+// idleNotifier 记录闲置通知
+type idleNotifier struct {
+	mu      sync.Mutex
+	notices map[string][]IdleMailboxNotice
+}
+
+func (n *idleNotifier) NotifyUser(userID, event string, data interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if event != UserEventMailboxesIdle {
+		return
+	}
+	if n.notices == nil {
+		n.notices = make(map[string][]IdleMailboxNotice)
+	}
+	n.notices[userID] = append(n.notices[userID], data.(IdleMailboxNotice))
+}
+
+type idleFixture struct {
+	store    *countingTouchStore
+	service  *MailboxIdleService
+	notifier *idleNotifier
+	now      time.Time
+}
+
+func newIdleFixture(t *testing.T, action string) *idleFixture {
+	t.Helper()
+	f := &idleFixture{
+		store:    &countingTouchStore{Store: memory.NewStore(0)},
+		notifier: &idleNotifier{},
+		now:      time.Now().UTC().Truncate(time.Second), // 内存存储按真实时间判断过期
+	}
+	config := domain.DefaultSystemConfig()
+	config.Idle = domain.IdlePolicyConfig{IdleAfter: "720h", WarnBefore: "72h", Action: action}
+	require.NoError(t, f.store.SaveSystemConfig(t.Context(), config))
+
+	f.service = NewMailboxIdleService(f.store)
+	f.service.now = func() time.Time { return f.now }
+	f.service.SetUserNotifier(f.notifier)
+	return f
+}
+
+func (f *idleFixture) addMailbox(t *testing.T, id string, userID *string, expiresAt time.Time) {
+	t.Helper()
+	require.NoError(t, f.store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: id, Address: id + "@corp.example", LocalPart: id, Domain: "corp.example", Token: "tok-" + id,
+		UserID: userID, CreatedAt: f.now, ExpiresAt: &expiresAt,
+	}))
+}
+
+func TestMailboxIdleService(t *testing.T) {
+	owner := "alice"
+
+	t.Run("闲置邮箱只提醒一次并按所有者合并", func(t *testing.T) {
+		f := newIdleFixture(t, domain.IdleActionWarn)
+		expires := f.now.Add(90 * 24 * time.Hour)
+		f.addMailbox(t, "a", &owner, expires)
+		f.addMailbox(t, "b", &owner, expires)
+		f.addMailbox(t, "guest", nil, expires)
+
+		f.now = f.now.Add(31 * 24 * time.Hour)
+		marked, err := f.service.CheckIdle(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 2, marked)
+		require.Len(t, f.notifier.notices[owner], 1)
+		notice := f.notifier.notices[owner][0]
+		require.Len(t, notice.Mailboxes, 2)
+		assert.Equal(t, "a@corp.example", notice.Mailboxes[0].Address)
+
+		mb, err := f.store.GetMailbox(t.Context(), "a")
+		require.NoError(t, err)
+		assert.NotNil(t, mb.IdleSince)
+		assert.Equal(t, expires, *mb.ExpiresAt, "warn 不修改有效期")
+
+		f.now = f.now.Add(24 * time.Hour)
+		marked, err = f.service.CheckIdle(t.Context())
+		require.NoError(t, err)
+		assert.Zero(t, marked)
+		assert.Len(t, f.notifier.notices[owner], 1)
+	})
+
+	t.Run("未到闲置时长不处理", func(t *testing.T) {
+		f := newIdleFixture(t, domain.IdleActionWarn)
+		f.addMailbox(t, "a", &owner, f.now.Add(90*24*time.Hour))
+
+		f.now = f.now.Add(29 * 24 * time.Hour)
+		marked, err := f.service.CheckIdle(t.Context())
+		require.NoError(t, err)
+		assert.Zero(t, marked)
+	})
+
+	t.Run("策略为 none 时不处理", func(t *testing.T) {
+		f := newIdleFixture(t, domain.IdleActionNone)
+		f.addMailbox(t, "a", &owner, f.now.Add(90*24*time.Hour))
+
+		f.now = f.now.Add(60 * 24 * time.Hour)
+		marked, err := f.service.CheckIdle(t.Context())
+		require.NoError(t, err)
+		assert.Zero(t, marked)
+		assert.Empty(t, f.notifier.notices)
+	})
+
+	t.Run("缩短有效期后访问恢复原值", func(t *testing.T) {
+		f := newIdleFixture(t, domain.IdleActionShortenTTL)
+		original := f.now.Add(90 * 24 * time.Hour)
+		f.addMailbox(t, "a", &owner, original)
+
+		f.now = f.now.Add(31 * 24 * time.Hour)
+		_, err := f.service.CheckIdle(t.Context())
+		require.NoError(t, err)
+		mb, err := f.store.GetMailbox(t.Context(), "a")
+		require.NoError(t, err)
+		assert.Equal(t, f.now.Add(72*time.Hour), *mb.ExpiresAt)
+		assert.Equal(t, f.now.Add(72*time.Hour), *f.notifier.notices[owner][0].Mailboxes[0].ExpiresAt)
+
+		f.now = f.now.Add(time.Hour)
+		require.NoError(t, f.service.Touch(t.Context(), "a"))
+		mb, err = f.store.GetMailbox(t.Context(), "a")
+		require.NoError(t, err)
+		assert.Equal(t, original, *mb.ExpiresAt)
+		assert.Nil(t, mb.IdleSince)
+		assert.Equal(t, f.now, *mb.LastAccessedAt)
+	})
+
+	t.Run("有效期本来更短时不延长", func(t *testing.T) {
+		f := newIdleFixture(t, domain.IdleActionShortenTTL)
+		f.addMailbox(t, "a", &owner, f.now.Add(31*24*time.Hour+time.Hour))
+
+		f.now = f.now.Add(31 * 24 * time.Hour)
+		_, err := f.service.CheckIdle(t.Context())
+		require.NoError(t, err)
+		mb, err := f.store.GetMailbox(t.Context(), "a")
+		require.NoError(t, err)
+		assert.Equal(t, f.now.Add(time.Hour), *mb.ExpiresAt)
+		assert.False(t, mb.IdleShortened)
+	})
+
+	t.Run("访问重置闲置计时", func(t *testing.T) {
+		f := newIdleFixture(t, domain.IdleActionWarn)
+		f.addMailbox(t, "a", &owner, f.now.Add(90*24*time.Hour))
+
+		f.now = f.now.Add(20 * 24 * time.Hour)
+		require.NoError(t, f.service.Touch(t.Context(), "a"))
+		f.now = f.now.Add(20 * 24 * time.Hour)
+		marked, err := f.service.CheckIdle(t.Context())
+		require.NoError(t, err)
+		assert.Zero(t, marked)
+	})
+
+	t.Run("访问时间每小时最多写一次", func(t *testing.T) {
+		f := newIdleFixture(t, domain.IdleActionWarn)
+		f.addMailbox(t, "a", &owner, f.now.Add(90*24*time.Hour))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, f.service.Touch(t.Context(), "a"))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, f.store.touches)
+
+		f.now = f.now.Add(59 * time.Minute)
+		require.NoError(t, f.service.Touch(t.Context(), "a"))
+		assert.Equal(t, 1, f.store.touches)
+
+		f.now = f.now.Add(time.Minute)
+		require.NoError(t, f.service.Touch(t.Context(), "a"))
+		assert.Equal(t, 2, f.store.touches)
+	})
+}
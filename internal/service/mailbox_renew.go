@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+var (
+	ErrRenewRequiresAccount    = errors.New("only mailboxes owned by a registered user can be extended")
+	ErrExtendDurationInvalid   = errors.New("extend duration must be positive")
+	ErrMailboxLifetimeExceeded = errors.New("mailbox lifetime limit of the user tier exceeded")
+)
+
+const (
+	// autoRenewWindow 到期时间在该时长内的自动续期邮箱由清理任务续期（需大于清理间隔）
+	autoRenewWindow = 2 * time.Hour
+	// fallbackRenewTTL 未配置默认有效期时每次续期的时长
+	fallbackRenewTTL = 24 * time.Hour
+)
+
+// Extend 延长用户邮箱的有效期
+//
+// 从当前过期时间（已过期或未设置时从现在）顺延 by，by 为 0 时使用默认有效期；
+// 延长后不能超过所属用户等级的最长生存时间（从创建时间算起）。续期同时清除闲置状态。
+func (s *MailboxService) Extend(ctx context.Context, id string, by time.Duration) (*domain.Mailbox, error) {
+	if by < 0 {
+		return nil, ErrExtendDurationInvalid
+	}
+	if by == 0 {
+		by = s.renewTTL()
+	}
+
+	mailbox, err := s.repo.GetMailbox(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if mailbox.UserID == nil {
+		return nil, ErrRenewRequiresAccount
+	}
+	limit, err := s.lifetimeLimit(ctx, mailbox)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := s.renewBase(mailbox, now).Add(by)
+	if limit != nil && expiresAt.After(*limit) {
+		return nil, ErrMailboxLifetimeExceeded
+	}
+	if err := s.repo.ExtendMailbox(ctx, id, expiresAt); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMailbox(ctx, id)
+}
+
+// SetAutoRenew 开启或关闭邮箱自动续期（仅用户邮箱）
+func (s *MailboxService) SetAutoRenew(ctx context.Context, id string, enabled bool) (*domain.Mailbox, error) {
+	mailbox, err := s.repo.GetMailbox(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if enabled && mailbox.UserID == nil {
+		return nil, ErrRenewRequiresAccount
+	}
+	mailbox.AutoRenew = enabled
+	if err := s.repo.SaveMailbox(ctx, mailbox); err != nil {
+		return nil, err
+	}
+	return mailbox, nil
+}
+
+// RenewExpiring 续期即将过期的自动续期邮箱，返回续期数量
+//
+// 由过期清理任务在删除前调用：每次按默认有效期顺延，达到用户等级的最长生存时间后
+// 截断到上限，之后不再续期，邮箱照常过期。闲置邮箱和查不到所属用户的邮箱跳过。
+func (s *MailboxService) RenewExpiring(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	ttl := s.renewTTL()
+
+	renewed := 0
+	var errs []error
+	for _, mb := range s.repo.ListMailboxes(ctx) {
+		if !mb.AutoRenew || mb.UserID == nil || mb.IdleSince != nil {
+			continue
+		}
+		base := s.renewBase(&mb, now)
+		if base.After(now.Add(autoRenewWindow)) {
+			continue
+		}
+		limit, err := s.lifetimeLimit(ctx, &mb)
+		if err != nil {
+			continue
+		}
+		expiresAt := base.Add(ttl)
+		if limit != nil && expiresAt.After(*limit) {
+			expiresAt = *limit
+		}
+		if !expiresAt.After(base) {
+			continue
+		}
+		if err := s.repo.ExtendMailbox(ctx, mb.ID, expiresAt); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		renewed++
+	}
+	return renewed, errors.Join(errs...)
+}
+
+// renewTTL 每次续期的时长
+func (s *MailboxService) renewTTL() time.Duration {
+	if ttl := s.DefaultTTL(); ttl > 0 {
+		return ttl
+	}
+	return fallbackRenewTTL
+}
+
+// renewBase 续期的起点：当前有效的过期时间，已过期时为现在
+//
+// 未设置过期时间的邮箱按创建时间加默认有效期计算（与内存存储的过期判断一致）；
+// 闲置时被缩短的有效期按缩短前的原值计算。
+func (s *MailboxService) renewBase(mailbox *domain.Mailbox, now time.Time) time.Time {
+	var expiresAt time.Time
+	switch {
+	case mailbox.IdleShortened && mailbox.IdleOriginalExpiresAt != nil:
+		expiresAt = *mailbox.IdleOriginalExpiresAt
+	case mailbox.ExpiresAt != nil:
+		expiresAt = *mailbox.ExpiresAt
+	default:
+		expiresAt = mailbox.CreatedAt.Add(s.DefaultTTL())
+	}
+	if expiresAt.Before(now) {
+		return now
+	}
+	return expiresAt
+}
+
+// lifetimeLimit 按所属用户等级计算的最晚过期时间，nil 表示无限制
+func (s *MailboxService) lifetimeLimit(ctx context.Context, mailbox *domain.Mailbox) (*time.Time, error) {
+	if s.store == nil || mailbox.UserID == nil {
+		return nil, nil
+	}
+	user, err := s.store.GetUserByID(ctx, *mailbox.UserID)
+	if err != nil {
+		return nil, err
+	}
+	lifetime := domain.DefaultQuotas(user.Tier).MaxMailboxLifetime()
+	if lifetime <= 0 {
+		return nil, nil
+	}
+	limit := mailbox.CreatedAt.Add(lifetime)
+	return &limit, nil
+}
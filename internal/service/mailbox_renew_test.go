@@ -0,0 +1,108 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestMailboxService_Renew(t *testing.T) {
+	newFixture := func(t *testing.T, tier domain.UserTier) (*MailboxService, *memory.Store, string) {
+		t.Helper()
+		store := memory.NewStore(time.Hour)
+		require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: "user-1", Email: "u@example.com", Tier: tier}))
+		cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.mail"}, DefaultTTL: time.Hour}}
+		return NewMailboxService(store, store, cfg), store, "user-1"
+	}
+	create := func(t *testing.T, svc *MailboxService, userID *string, createdAgo time.Duration, expiresIn time.Duration) *domain.Mailbox {
+		t.Helper()
+		// 直接写入存储：免费等级的邮箱数量配额不影响续期场景
+		mailbox, err := svc.buildMailbox(CreateMailboxInput{UserID: userID}, "temp.mail")
+		require.NoError(t, err)
+		mailbox.CreatedAt = time.Now().UTC().Add(-createdAgo)
+		expiresAt := time.Now().UTC().Add(expiresIn)
+		mailbox.ExpiresAt = &expiresAt
+		require.NoError(t, svc.repo.SaveMailbox(t.Context(), mailbox))
+		return mailbox
+	}
+
+	t.Run("延长有效期并清除闲置状态", func(t *testing.T) {
+		svc, store, userID := newFixture(t, domain.TierFree)
+		mailbox := create(t, svc, &userID, 0, 30*time.Minute)
+		require.NoError(t, store.MarkMailboxIdle(t.Context(), mailbox.ID, time.Now(), nil))
+		before := *mailbox.ExpiresAt
+
+		extended, err := svc.Extend(t.Context(), mailbox.ID, 2*time.Hour)
+		require.NoError(t, err)
+		assert.WithinDuration(t, before.Add(2*time.Hour), *extended.ExpiresAt, time.Second)
+		assert.Nil(t, extended.IdleSince)
+
+		// 时长为 0 时按默认有效期延长
+		extended, err = svc.Extend(t.Context(), mailbox.ID, 0)
+		require.NoError(t, err)
+		assert.WithinDuration(t, before.Add(3*time.Hour), *extended.ExpiresAt, time.Second)
+	})
+
+	t.Run("不能超过用户等级的最长生存时间", func(t *testing.T) {
+		svc, _, userID := newFixture(t, domain.TierFree)
+		mailbox := create(t, svc, &userID, 20*time.Hour, time.Hour)
+
+		_, err := svc.Extend(t.Context(), mailbox.ID, 4*time.Hour)
+		assert.ErrorIs(t, err, ErrMailboxLifetimeExceeded)
+
+		_, err = svc.Extend(t.Context(), mailbox.ID, 2*time.Hour)
+		assert.NoError(t, err)
+	})
+
+	t.Run("游客邮箱不能续期", func(t *testing.T) {
+		svc, _, _ := newFixture(t, domain.TierFree)
+		mailbox := create(t, svc, nil, 0, time.Hour)
+
+		_, err := svc.Extend(t.Context(), mailbox.ID, time.Hour)
+		assert.ErrorIs(t, err, ErrRenewRequiresAccount)
+		_, err = svc.SetAutoRenew(t.Context(), mailbox.ID, true)
+		assert.ErrorIs(t, err, ErrRenewRequiresAccount)
+		_, err = svc.Create(t.Context(), CreateMailboxInput{AutoRenew: true})
+		assert.ErrorIs(t, err, ErrRenewRequiresAccount)
+	})
+
+	t.Run("清理任务续期即将过期的自动续期邮箱", func(t *testing.T) {
+		svc, _, userID := newFixture(t, domain.TierFree)
+		expiring := create(t, svc, &userID, 0, 30*time.Minute)
+		_, err := svc.SetAutoRenew(t.Context(), expiring.ID, true)
+		require.NoError(t, err)
+		notRenewing := create(t, svc, &userID, 0, 30*time.Minute)
+		later := create(t, svc, &userID, 0, 5*time.Hour)
+		_, err = svc.SetAutoRenew(t.Context(), later.ID, true)
+		require.NoError(t, err)
+		// 接近上限的邮箱截断到最长生存时间
+		nearLimit := create(t, svc, &userID, 23*time.Hour+30*time.Minute, 20*time.Minute)
+		_, err = svc.SetAutoRenew(t.Context(), nearLimit.ID, true)
+		require.NoError(t, err)
+
+		renewed, err := svc.RenewExpiring(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 2, renewed)
+
+		got, err := svc.Get(t.Context(), expiring.ID)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(90*time.Minute), *got.ExpiresAt, time.Second)
+		got, err = svc.Get(t.Context(), notRenewing.ID)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(30*time.Minute), *got.ExpiresAt, time.Second)
+		got, err = svc.Get(t.Context(), nearLimit.ID)
+		require.NoError(t, err)
+		assert.WithinDuration(t, nearLimit.CreatedAt.Add(24*time.Hour), *got.ExpiresAt, time.Second)
+
+		// 已到上限，不再续期
+		renewed, err = svc.RenewExpiring(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 1, renewed)
+	})
+}
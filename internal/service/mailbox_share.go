@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/domain"
+)
+
+var ErrShareMailboxNotFound = errors.New("mailbox to share not found")
+
+// SharedMessagePreview 分享邮箱中的邮件预览
+type SharedMessagePreview struct {
+	ID             string    `json:"id"`
+	From           string    `json:"from"`
+	Subject        string    `json:"subject"`
+	Preview        string    `json:"preview"`
+	HasAttachments bool      `json:"hasAttachments"`
+	ReceivedAt     time.Time `json:"receivedAt"`
+}
+
+// SharedMailbox 通过分享链接查看的邮箱（只有地址和邮件，不含令牌等其他信息）
+type SharedMailbox struct {
+	Address          string                 `json:"address"`
+	ExpiresAt        time.Time              `json:"expiresAt"` // 链接过期时间
+	AllowAttachments bool                   `json:"allowAttachments"`
+	Items            []SharedMessagePreview `json:"items"`
+	Total            int                    `json:"total"`
+	Page             int                    `json:"page"`
+	PageSize         int                    `json:"pageSize"`
+}
+
+// CreateMailbox 为整个邮箱创建只读分享链接（Redacted 选项不适用，忽略）
+func (s *MessageShareService) CreateMailbox(ctx context.Context, mailboxID string, input CreateShareInput) (*MessageShareLink, error) {
+	ttl := input.TTL
+	if ttl == 0 {
+		ttl = DefaultShareTTL
+	}
+	if ttl < MinShareTTL || ttl > MaxShareTTL {
+		return nil, ErrShareExpiryInvalid
+	}
+	if _, err := s.store.GetMailbox(ctx, mailboxID); err != nil {
+		return nil, ErrShareMailboxNotFound
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	share := &domain.MessageShare{
+		ID:               uuid.NewString(),
+		MailboxID:        mailboxID,
+		Nonce:            base64.RawURLEncoding.EncodeToString(nonce),
+		AllowAttachments: input.AllowAttachments,
+		ExpiresAt:        now.Add(ttl).Truncate(time.Second),
+		CreatedAt:        now,
+	}
+	if err := s.store.SaveMessageShare(ctx, share); err != nil {
+		return nil, err
+	}
+	return s.link(share)
+}
+
+// OpenMailbox 按链接令牌分页列出邮箱中的邮件（新邮件在前，不含隔离区）并计入访问次数
+func (s *MessageShareService) OpenMailbox(ctx context.Context, token string, page, pageSize int) (*SharedMailbox, error) {
+	share, err := s.verify(ctx, token, true)
+	if err != nil {
+		return nil, err
+	}
+	mailbox, err := s.store.GetMailbox(ctx, share.MailboxID)
+	if err != nil {
+		return nil, ErrShareLinkInvalid
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPublicPageSize
+	}
+	pageSize = min(pageSize, MaxPublicPageSize)
+	result, err := s.messages.ListPage(ctx, ListPageInput{MailboxID: mailbox.ID, Limit: pageSize, Offset: (page - 1) * pageSize})
+	if err != nil {
+		return nil, err
+	}
+
+	shared := &SharedMailbox{
+		Address:          mailbox.Address,
+		ExpiresAt:        share.ExpiresAt,
+		AllowAttachments: share.AllowAttachments,
+		Items:            make([]SharedMessagePreview, 0, len(result.Messages)),
+		Total:            result.Total,
+		Page:             page,
+		PageSize:         pageSize,
+	}
+	for _, msg := range result.Messages {
+		shared.Items = append(shared.Items, SharedMessagePreview{
+			ID:             msg.ID,
+			From:           msg.From,
+			Subject:        msg.Subject,
+			Preview:        Preview(&msg),
+			HasAttachments: len(msg.Attachments) > 0,
+			ReceivedAt:     msg.ReceivedAt,
+		})
+	}
+
+	// 计数失败不影响查看
+	_ = s.store.RecordMessageShareView(ctx, share.ID, s.now())
+	return shared, nil
+}
+
+// OpenMailboxMessage 按邮箱分享链接查看其中一封邮件（不标记已读）并计入访问次数
+func (s *MessageShareService) OpenMailboxMessage(ctx context.Context, token, messageID string) (*SharedMessage, error) {
+	share, err := s.verify(ctx, token, true)
+	if err != nil {
+		return nil, err
+	}
+	message, err := s.messages.Get(ctx, share.MailboxID, messageID)
+	if err != nil || message.Quarantined {
+		return nil, ErrShareMessageNotFound
+	}
+	result, err := s.sharedMessage(ctx, share, message)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.store.RecordMessageShareView(ctx, share.ID, s.now())
+	return result, nil
+}
+
+// OpenMailboxAttachment 按邮箱分享链接获取附件（链接不允许下载附件时返回 ErrShareAttachmentsNotAllowed）
+func (s *MessageShareService) OpenMailboxAttachment(ctx context.Context, token, messageID, attachmentID string) (*domain.Attachment, error) {
+	share, err := s.verify(ctx, token, true)
+	if err != nil {
+		return nil, err
+	}
+	if !share.AllowAttachments {
+		return nil, ErrShareAttachmentsNotAllowed
+	}
+	message, err := s.store.GetMessage(ctx, share.MailboxID, messageID)
+	if err != nil || message.Quarantined {
+		return nil, ErrShareMessageNotFound
+	}
+	return s.messages.GetAttachment(ctx, share.MailboxID, message.ID, attachmentID)
+}
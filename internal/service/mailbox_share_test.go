@@ -0,0 +1,94 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageShareService_Mailbox(t *testing.T) {
+	t.Run("凭链接只读查看邮箱中的邮件", func(t *testing.T) {
+		f := newShareFixture(t)
+		_, err := f.shares.CreateMailbox(t.Context(), "missing", CreateShareInput{})
+		assert.ErrorIs(t, err, ErrShareMailboxNotFound)
+		_, err = f.shares.CreateMailbox(t.Context(), f.mailbox.ID, CreateShareInput{TTL: MaxShareTTL + time.Hour})
+		assert.ErrorIs(t, err, ErrShareExpiryInvalid)
+
+		link, err := f.shares.CreateMailbox(t.Context(), f.mailbox.ID, CreateShareInput{TTL: time.Hour})
+		require.NoError(t, err)
+		require.NotEmpty(t, link.Token)
+		assert.True(t, link.MailboxWide())
+		assert.NotContains(t, link.Token, f.mailbox.Token)
+
+		shared, err := f.shares.OpenMailbox(t.Context(), link.Token, 1, 0)
+		require.NoError(t, err)
+		assert.Equal(t, f.mailbox.Address, shared.Address)
+		require.Len(t, shared.Items, 1)
+		assert.Equal(t, f.message.ID, shared.Items[0].ID)
+
+		message, err := f.shares.OpenMailboxMessage(t.Context(), link.Token, f.message.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Invoice 42", message.Subject)
+		assert.NotContains(t, message.HTML, "onclick")
+		stored, err := f.store.GetMessage(t.Context(), f.mailbox.ID, f.message.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.IsRead, "查看不标记已读")
+
+		_, err = f.shares.OpenMailboxMessage(t.Context(), link.Token, "missing")
+		assert.ErrorIs(t, err, ErrShareMessageNotFound)
+		_, err = f.shares.OpenMailboxAttachment(t.Context(), link.Token, f.message.ID, "att-1")
+		assert.ErrorIs(t, err, ErrShareAttachmentsNotAllowed)
+
+		shares, err := f.shares.List(t.Context(), f.mailbox.ID)
+		require.NoError(t, err)
+		require.Len(t, shares, 1)
+		assert.Equal(t, int64(2), shares[0].Views)
+	})
+
+	t.Run("隔离区邮件不可见", func(t *testing.T) {
+		f := newShareFixture(t)
+		link, err := f.shares.CreateMailbox(t.Context(), f.mailbox.ID, CreateShareInput{AllowAttachments: true})
+		require.NoError(t, err)
+		require.NoError(t, f.store.SetMessageQuarantined(t.Context(), f.mailbox.ID, f.message.ID, true))
+
+		shared, err := f.shares.OpenMailbox(t.Context(), link.Token, 1, 20)
+		require.NoError(t, err)
+		assert.Empty(t, shared.Items)
+		_, err = f.shares.OpenMailboxMessage(t.Context(), link.Token, f.message.ID)
+		assert.ErrorIs(t, err, ErrShareMessageNotFound)
+		_, err = f.shares.OpenMailboxAttachment(t.Context(), link.Token, f.message.ID, "att-1")
+		assert.ErrorIs(t, err, ErrShareMessageNotFound)
+	})
+
+	t.Run("撤销和过期", func(t *testing.T) {
+		f := newShareFixture(t)
+		link, err := f.shares.CreateMailbox(t.Context(), f.mailbox.ID, CreateShareInput{TTL: time.Hour})
+		require.NoError(t, err)
+		_, err = f.shares.Revoke(t.Context(), f.mailbox.ID, link.ID)
+		require.NoError(t, err)
+		_, err = f.shares.OpenMailbox(t.Context(), link.Token, 1, 20)
+		assert.ErrorIs(t, err, ErrShareLinkExpired)
+
+		link, err = f.shares.CreateMailbox(t.Context(), f.mailbox.ID, CreateShareInput{TTL: time.Hour})
+		require.NoError(t, err)
+		f.now = f.now.Add(time.Hour + time.Second)
+		_, err = f.shares.OpenMailboxMessage(t.Context(), link.Token, f.message.ID)
+		assert.ErrorIs(t, err, ErrShareLinkExpired)
+	})
+
+	t.Run("邮箱链接和邮件链接不能互换", func(t *testing.T) {
+		f := newShareFixture(t)
+		mailboxLink, err := f.shares.CreateMailbox(t.Context(), f.mailbox.ID, CreateShareInput{})
+		require.NoError(t, err)
+		messageLink := f.create(t, CreateShareInput{})
+
+		_, err = f.shares.Open(t.Context(), mailboxLink.Token)
+		assert.ErrorIs(t, err, ErrShareLinkInvalid)
+		_, err = f.shares.OpenMailbox(t.Context(), messageLink.Token, 1, 20)
+		assert.ErrorIs(t, err, ErrShareLinkInvalid)
+		_, err = f.shares.OpenMailboxMessage(t.Context(), messageLink.Token, f.message.ID)
+		assert.ErrorIs(t, err, ErrShareLinkInvalid)
+	})
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/spam"
+	"tempmail/backend/internal/storage"
+)
+
+var ErrSpamThresholdInvalid = errors.New("spam threshold out of range")
+
+// UpdateSpamThresholdsInput 邮箱自定义垃圾邮件阈值（nil 表示不修改，0 表示恢复系统默认）
+type UpdateSpamThresholdsInput struct {
+	QuarantineScore *float64
+	RejectScore     *float64
+}
+
+// UpdateSpamThresholds 设置邮箱的垃圾邮件阈值
+//
+// 用于专门接收“垃圾”测试邮件的 QA 邮箱调高容忍度；阈值不能超过系统配置的上限，
+// 且生效后的软阈值必须低于硬阈值。
+func (s *MailboxService) UpdateSpamThresholds(ctx context.Context, id string, input UpdateSpamThresholdsInput) (*domain.Mailbox, error) {
+	mailbox, err := s.repo.GetMailbox(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	quarantine, reject, err := applySpamThresholds(s.cfg.Spam, mailbox.SpamQuarantineScore, mailbox.SpamRejectScore, input)
+	if err != nil {
+		return nil, err
+	}
+
+	mailbox.SpamQuarantineScore, mailbox.SpamRejectScore = quarantine, reject
+	if err := s.repo.SaveMailbox(ctx, mailbox); err != nil {
+		return nil, err
+	}
+	return mailbox, nil
+}
+
+// UserSpamService 用户级垃圾邮件阈值（作为名下邮箱的默认值，邮箱自定义阈值优先）
+type UserSpamService struct {
+	users storage.UserRepository
+	cfg   *config.Config
+}
+
+// NewUserSpamService 创建用户垃圾邮件阈值服务
+func NewUserSpamService(users storage.UserRepository, cfg *config.Config) *UserSpamService {
+	return &UserSpamService{users: users, cfg: cfg}
+}
+
+// UpdateThresholds 设置用户的垃圾邮件阈值，校验规则与邮箱阈值相同
+func (s *UserSpamService) UpdateThresholds(ctx context.Context, userID string, input UpdateSpamThresholdsInput) (*domain.User, error) {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	quarantine, reject, err := applySpamThresholds(s.cfg.Spam, user.SpamQuarantineScore, user.SpamRejectScore, input)
+	if err != nil {
+		return nil, err
+	}
+
+	user.SpamQuarantineScore, user.SpamRejectScore = quarantine, reject
+	if err := s.users.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// applySpamThresholds 在当前自定义阈值上应用修改，校验上限和软硬阈值顺序
+func applySpamThresholds(limits config.SpamConfig, quarantine, reject *float64, input UpdateSpamThresholdsInput) (*float64, *float64, error) {
+	maxScore := limits.MaxMailboxScore
+	if maxScore <= 0 {
+		maxScore = spam.DefaultMaxMailboxScore
+	}
+
+	var err error
+	if input.QuarantineScore != nil {
+		if quarantine, err = spamOverride(*input.QuarantineScore, maxScore); err != nil {
+			return nil, nil, err
+		}
+	}
+	if input.RejectScore != nil {
+		if reject, err = spamOverride(*input.RejectScore, maxScore); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	effectiveQuarantine, effectiveReject := limits.QuarantineScore, limits.RejectScore
+	if effectiveQuarantine <= 0 {
+		effectiveQuarantine = spam.DefaultQuarantineScore
+	}
+	if effectiveReject <= 0 {
+		effectiveReject = spam.DefaultRejectScore
+	}
+	if quarantine != nil {
+		effectiveQuarantine = *quarantine
+	}
+	if reject != nil {
+		effectiveReject = *reject
+	}
+	if effectiveQuarantine >= effectiveReject {
+		return nil, nil, ErrSpamThresholdInvalid
+	}
+	return quarantine, reject, nil
+}
+
+// spamOverride 校验单个阈值：0 清除自定义值，其余必须在 (0, maxScore] 内
+func spamOverride(value, maxScore float64) (*float64, error) {
+	if value == 0 {
+		return nil, nil
+	}
+	if value < 0 || value > maxScore {
+		return nil, ErrSpamThresholdInvalid
+	}
+	return &value, nil
+}
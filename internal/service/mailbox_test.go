@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -17,12 +19,12 @@ type MockStore struct {
 	mock.Mock
 }
 
-func (m *MockStore) SaveMailbox(mailbox *domain.Mailbox) error {
+func (m *MockStore) SaveMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
 	args := m.Called(mailbox)
 	return args.Error(0)
 }
 
-func (m *MockStore) GetMailbox(id string) (*domain.Mailbox, error) {
+func (m *MockStore) GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -30,7 +32,7 @@ func (m *MockStore) GetMailbox(id string) (*domain.Mailbox, error) {
 	return args.Get(0).(*domain.Mailbox), args.Error(1)
 }
 
-func (m *MockStore) GetMailboxByAddress(address string) (*domain.Mailbox, error) {
+func (m *MockStore) GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error) {
 	args := m.Called(address)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -38,61 +40,62 @@ func (m *MockStore) GetMailboxByAddress(address string) (*domain.Mailbox, error)
 	return args.Get(0).(*domain.Mailbox), args.Error(1)
 }
 
-func (m *MockStore) ListMailboxes() []domain.Mailbox {
+func (m *MockStore) ListMailboxes(ctx context.Context) []domain.Mailbox {
 	args := m.Called()
 	return args.Get(0).([]domain.Mailbox)
 }
 
-func (m *MockStore) DeleteMailbox(id string) error {
+func (m *MockStore) DeleteMailbox(ctx context.Context, id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockStore) ListMailboxesByUserID(userID string) []domain.Mailbox {
+func (m *MockStore) ListMailboxesByUserID(ctx context.Context, userID string) []domain.Mailbox {
 	args := m.Called(userID)
 	return args.Get(0).([]domain.Mailbox)
 }
 
-func (m *MockStore) DeleteExpiredMailboxes() (int, error) {
+func (m *MockStore) DeleteExpiredMailboxes(ctx context.Context) (int, error) {
 	args := m.Called()
 	return args.Int(0), args.Error(1)
 }
 
 // 实现其他必需的接口方法（简化版）
-func (m *MockStore) SaveMessage(message *domain.Message) error { return nil }
-func (m *MockStore) ListMessages(mailboxID string) ([]domain.Message, error) { return nil, nil }
-func (m *MockStore) GetMessage(mailboxID, messageID string) (*domain.Message, error) { return nil, nil }
-func (m *MockStore) MarkMessageRead(mailboxID, messageID string) error { return nil }
+func (m *MockStore) SaveMessage(ctx context.Context, message *domain.Message) error { return nil }
+func (m *MockStore) ListMessages(ctx context.Context, mailboxID string) ([]domain.Message, error) { return nil, nil }
+func (m *MockStore) GetMessage(ctx context.Context, mailboxID, messageID string) (*domain.Message, error) { return nil, nil }
+func (m *MockStore) MarkMessageRead(ctx context.Context, mailboxID, messageID string) error { return nil }
+func (m *MockStore) MarkMessageUnread(ctx context.Context, mailboxID, messageID string) error { return nil }
 func (m *MockStore) CreateUser(user *domain.User) error { return nil }
-func (m *MockStore) GetUserByID(id string) (*domain.User, error) { return nil, nil }
+func (m *MockStore) GetUserByID(ctx context.Context, id string) (*domain.User, error) { return nil, nil }
 func (m *MockStore) GetUserByEmail(email string) (*domain.User, error) { return nil, nil }
 func (m *MockStore) UpdateUser(user *domain.User) error { return nil }
 func (m *MockStore) UpdateLastLogin(userID string) error { return nil }
 func (m *MockStore) GetUserByAPIKey(apiKey string) (*domain.User, error) { return nil, nil }
-func (m *MockStore) SaveAPIKey(apiKey *domain.APIKey) error { return nil }
-func (m *MockStore) GetAPIKey(id string) (*domain.APIKey, error) { return nil, nil }
-func (m *MockStore) GetAPIKeyByKey(key string) (*domain.APIKey, error) { return nil, nil }
-func (m *MockStore) ListAPIKeysByUserID(userID string) ([]*domain.APIKey, error) { return nil, nil }
-func (m *MockStore) DeleteAPIKey(id string) error { return nil }
-func (m *MockStore) UpdateAPIKeyLastUsed(id string) error { return nil }
-func (m *MockStore) ListUsers(page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) { return nil, 0, nil }
-func (m *MockStore) DeleteUser(userID string) error { return nil }
-func (m *MockStore) DeleteMailboxesByUserID(userID string) error { return nil }
-func (m *MockStore) GetSystemStatistics() (*domain.SystemStatistics, error) { return nil, nil }
-func (m *MockStore) GetDomainStatistics(domain string) (int, int, error) { return 0, 0, nil }
-func (m *MockStore) SaveAlias(alias *domain.MailboxAlias) error { return nil }
-func (m *MockStore) GetAlias(aliasID string) (*domain.MailboxAlias, error) { return nil, nil }
-func (m *MockStore) GetAliasByAddress(address string) (*domain.MailboxAlias, error) { return nil, nil }
-func (m *MockStore) ListAliasesByMailboxID(mailboxID string) ([]*domain.MailboxAlias, error) { return nil, nil }
-func (m *MockStore) DeleteAlias(aliasID string) error { return nil }
-func (m *MockStore) AddToBlacklist(jti string, ttl time.Duration) error { return nil }
-func (m *MockStore) IsBlacklisted(jti string) (bool, error) { return false, nil }
-func (m *MockStore) IncrementRateLimit(key string, window time.Duration) (int64, error) { return 0, nil }
+func (m *MockStore) SaveAPIKey(ctx context.Context, apiKey *domain.APIKey) error { return nil }
+func (m *MockStore) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) { return nil, nil }
+func (m *MockStore) GetAPIKeyByKey(ctx context.Context, key string) (*domain.APIKey, error) { return nil, nil }
+func (m *MockStore) ListAPIKeysByUserID(ctx context.Context, userID string) ([]*domain.APIKey, error) { return nil, nil }
+func (m *MockStore) DeleteAPIKey(ctx context.Context, id string) error { return nil }
+func (m *MockStore) UpdateAPIKeyLastUsed(ctx context.Context, id string) error { return nil }
+func (m *MockStore) ListUsers(ctx context.Context, page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) { return nil, 0, nil }
+func (m *MockStore) DeleteUser(ctx context.Context, userID string) error { return nil }
+func (m *MockStore) DeleteMailboxesByUserID(ctx context.Context, userID string) error { return nil }
+func (m *MockStore) GetSystemStatistics(ctx context.Context) (*domain.SystemStatistics, error) { return nil, nil }
+func (m *MockStore) GetDomainStatistics(ctx context.Context, domain string) (int, int, error) { return 0, 0, nil }
+func (m *MockStore) SaveAlias(ctx context.Context, alias *domain.MailboxAlias) error { return nil }
+func (m *MockStore) GetAlias(ctx context.Context, aliasID string) (*domain.MailboxAlias, error) { return nil, nil }
+func (m *MockStore) GetAliasByAddress(ctx context.Context, address string) (*domain.MailboxAlias, error) { return nil, nil }
+func (m *MockStore) ListAliasesByMailboxID(ctx context.Context, mailboxID string) ([]*domain.MailboxAlias, error) { return nil, nil }
+func (m *MockStore) DeleteAlias(ctx context.Context, aliasID string) error { return nil }
+func (m *MockStore) AddToBlacklist(ctx context.Context, jti string, ttl time.Duration) error { return nil }
+func (m *MockStore) IsBlacklisted(ctx context.Context, jti string) (bool, error) { return false, nil }
+func (m *MockStore) IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error) { return 0, nil }
 func (m *MockStore) GetRateLimit(key string) (int64, error) { return 0, nil }
-func (m *MockStore) CacheSession(sessionID string, userID string, ttl time.Duration) error { return nil }
-func (m *MockStore) GetCachedSession(sessionID string) (string, error) { return "", nil }
-func (m *MockStore) DeleteCachedSession(sessionID string) error { return nil }
-func (m *MockStore) PublishNewMail(mailboxID string, message *domain.Message) error { return nil }
+func (m *MockStore) CacheSession(ctx context.Context, sessionID string, userID string, ttl time.Duration) error { return nil }
+func (m *MockStore) GetCachedSession(ctx context.Context, sessionID string) (string, error) { return "", nil }
+func (m *MockStore) DeleteCachedSession(ctx context.Context, sessionID string) error { return nil }
+func (m *MockStore) PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error { return nil }
 func (m *MockStore) SubscribeNewMail(mailboxID string) interface{} { return nil }
 func (m *MockStore) Close() error { return nil }
 func (m *MockStore) Health() error { return nil }
@@ -115,7 +118,7 @@ func TestMailboxService_CreateRandomMailbox(t *testing.T) {
 			IPSource: "192.168.1.1",
 		}
 
-		mailbox, err := service.Create(input)
+		mailbox, err := service.Create(t.Context(), input)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, mailbox)
@@ -135,7 +138,7 @@ func TestMailboxService_CreateRandomMailbox(t *testing.T) {
 			IPSource: "192.168.1.1",
 		}
 
-		mailbox, err := service.Create(input)
+		mailbox, err := service.Create(t.Context(), input)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, mailbox)
@@ -151,7 +154,7 @@ func TestMailboxService_CreateRandomMailbox(t *testing.T) {
 			IPSource: "192.168.1.1",
 		}
 
-		mailbox, err := service.Create(input)
+		mailbox, err := service.Create(t.Context(), input)
 
 		assert.Error(t, err)
 		assert.Nil(t, mailbox)
@@ -165,7 +168,7 @@ func TestMailboxService_CreateRandomMailbox(t *testing.T) {
 			IPSource: "192.168.1.1",
 		}
 
-		mailbox, err := service.Create(input)
+		mailbox, err := service.Create(t.Context(), input)
 
 		assert.Error(t, err)
 		assert.Nil(t, mailbox)
@@ -188,11 +191,11 @@ func TestMailboxService_GetMailbox(t *testing.T) {
 	input := CreateMailboxInput{
 		IPSource: "192.168.1.1",
 	}
-	createdMailbox, err := service.Create(input)
+	createdMailbox, err := service.Create(t.Context(), input)
 	assert.NoError(t, err)
 
 	t.Run("根据ID获取邮箱成功", func(t *testing.T) {
-		mailbox, err := service.Get(createdMailbox.ID)
+		mailbox, err := service.Get(t.Context(), createdMailbox.ID)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, mailbox)
@@ -201,7 +204,7 @@ func TestMailboxService_GetMailbox(t *testing.T) {
 	})
 
 	t.Run("根据地址获取邮箱成功", func(t *testing.T) {
-		mailbox, err := service.GetByAddress(createdMailbox.Address)
+		mailbox, err := service.GetByAddress(t.Context(), createdMailbox.Address)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, mailbox)
@@ -210,7 +213,7 @@ func TestMailboxService_GetMailbox(t *testing.T) {
 	})
 
 	t.Run("获取不存在的邮箱失败", func(t *testing.T) {
-		mailbox, err := service.Get("nonexistent")
+		mailbox, err := service.Get(t.Context(), "nonexistent")
 
 		assert.Error(t, err)
 		assert.Nil(t, mailbox)
@@ -232,22 +235,22 @@ func TestMailboxService_DeleteMailbox(t *testing.T) {
 	input := CreateMailboxInput{
 		IPSource: "192.168.1.1",
 	}
-	createdMailbox, err := service.Create(input)
+	createdMailbox, err := service.Create(t.Context(), input)
 	assert.NoError(t, err)
 
 	t.Run("删除邮箱成功", func(t *testing.T) {
-		err := service.Delete(createdMailbox.ID)
+		err := service.Delete(t.Context(), createdMailbox.ID)
 
 		assert.NoError(t, err)
 
 		// 验证邮箱已被删除
-		mailbox, err := service.Get(createdMailbox.ID)
+		mailbox, err := service.Get(t.Context(), createdMailbox.ID)
 		assert.Error(t, err)
 		assert.Nil(t, mailbox)
 	})
 
 	t.Run("删除不存在的邮箱失败", func(t *testing.T) {
-		err := service.Delete("nonexistent")
+		err := service.Delete(t.Context(), "nonexistent")
 
 		assert.Error(t, err)
 	})
@@ -269,7 +272,7 @@ func TestMailboxService_CreateWithDifferentInputs(t *testing.T) {
 			IPSource: "192.168.1.1",
 		}
 
-		mailbox, err := service.Create(input)
+		mailbox, err := service.Create(t.Context(), input)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, mailbox)
@@ -280,11 +283,63 @@ func TestMailboxService_CreateWithDifferentInputs(t *testing.T) {
 		input1 := CreateMailboxInput{IPSource: "192.168.1.1"}
 		input2 := CreateMailboxInput{IPSource: "192.168.1.2"}
 
-		mailbox1, err1 := service.Create(input1)
-		mailbox2, err2 := service.Create(input2)
+		mailbox1, err1 := service.Create(t.Context(), input1)
+		mailbox2, err2 := service.Create(t.Context(), input2)
 
 		assert.NoError(t, err1)
 		assert.NoError(t, err2)
 		assert.NotEqual(t, mailbox1.Address, mailbox2.Address)
 	})
-}
\ No newline at end of file
+}
+func TestMailboxService_CreateAddressTaken(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	cfg := &config.Config{
+		Mailbox: config.MailboxConfig{
+			AllowedDomains: []string{"temp.mail"},
+			DefaultTTL:     24 * time.Hour,
+		},
+	}
+
+	service := NewMailboxService(store, store, cfg)
+	for _, prefix := range []string{"taken1", "taken2", "taken3", "taken4", "taken5"} {
+		_, err := service.Create(t.Context(), CreateMailboxInput{Prefix: prefix, Domain: "temp.mail"})
+		assert.NoError(t, err)
+	}
+
+	t.Run("指定前缀冲突直接返回", func(t *testing.T) {
+		mailbox, err := service.Create(t.Context(), CreateMailboxInput{Prefix: "taken1", Domain: "temp.mail"})
+		assert.ErrorIs(t, err, ErrAddressTaken)
+		assert.Nil(t, mailbox)
+	})
+
+	t.Run("随机前缀冲突时换一个重试", func(t *testing.T) {
+		candidates := []string{"taken1", "taken2", "fresh1"}
+		calls := 0
+		service.newLocalPart = func() string {
+			calls++
+			return candidates[calls-1]
+		}
+
+		mailbox, err := service.Create(t.Context(), CreateMailboxInput{Domain: "temp.mail"})
+		assert.NoError(t, err)
+		assert.Equal(t, "fresh1@temp.mail", mailbox.Address)
+		assert.Equal(t, 3, calls)
+
+		stored, err := store.GetMailboxByAddress(t.Context(), "taken1@temp.mail")
+		assert.NoError(t, err)
+		assert.NotEqual(t, mailbox.ID, stored.ID)
+	})
+
+	t.Run("随机前缀重试次数用尽", func(t *testing.T) {
+		calls := 0
+		service.newLocalPart = func() string {
+			calls++
+			return fmt.Sprintf("taken%d", calls)
+		}
+
+		mailbox, err := service.Create(t.Context(), CreateMailboxInput{Domain: "temp.mail"})
+		assert.ErrorIs(t, err, ErrAddressTaken)
+		assert.Nil(t, mailbox)
+		assert.Equal(t, randomAddressAttempts, calls)
+	})
+}
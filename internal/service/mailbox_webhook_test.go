@@ -0,0 +1,181 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/security"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// eventRecorder 按请求路径记录收到的事件类型
+type eventRecorder struct {
+	mu     sync.Mutex
+	events map[string][]string
+}
+
+func (r *eventRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	if r.events == nil {
+		r.events = make(map[string][]string)
+	}
+	r.events[req.URL.Path] = append(r.events[req.URL.Path], req.Header.Get("X-Webhook-Event"))
+	r.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *eventRecorder) get(path string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events[path]...)
+}
+
+// fixedGuestPolicy 固定的邮箱 Webhook 限制
+type fixedGuestPolicy domain.GuestWebhookConfig
+
+func (p fixedGuestPolicy) GuestWebhooks() domain.GuestWebhookConfig {
+	return domain.GuestWebhookConfig(p)
+}
+
+func TestMailboxWebhooks(t *testing.T) {
+	setup := func(t *testing.T) (*memory.Store, *WebhookService, *eventRecorder, string) {
+		t.Helper()
+		store := memory.NewStore(24 * time.Hour)
+		recorder := &eventRecorder{}
+		server := httptest.NewServer(recorder)
+		t.Cleanup(server.Close)
+
+		webhooks := NewWebhookService(store)
+		// 测试接收方在回环地址上
+		webhooks.guestClient = server.Client()
+		webhooks.validateURL = func(raw string) error {
+			if strings.HasPrefix(raw, server.URL) {
+				return nil
+			}
+			return security.ValidatePublicURL(raw)
+		}
+
+		userID := "user-1"
+		expires := time.Now().Add(time.Hour)
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-user", Address: "u@temp.mail", UserID: &userID, CreatedAt: time.Now(), ExpiresAt: &expires}))
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-guest", Address: "g@temp.mail", CreatedAt: time.Now(), ExpiresAt: &expires}))
+		return store, webhooks, recorder, server.URL
+	}
+
+	t.Run("校验事件、回调地址和数量上限", func(t *testing.T) {
+		_, webhooks, _, _ := setup(t)
+		webhooks.SetGuestPolicy(fixedGuestPolicy{MaxPerMailbox: 2})
+
+		_, err := webhooks.CreateMailboxWebhook(t.Context(), "mb-guest", CreateMailboxWebhookInput{URL: "https://hooks.example.com/a", Events: []string{"tag.created"}})
+		assert.ErrorIs(t, err, ErrMailboxWebhookEvent)
+		_, err = webhooks.CreateMailboxWebhook(t.Context(), "mb-guest", CreateMailboxWebhookInput{URL: "http://169.254.169.254/latest"})
+		assert.ErrorIs(t, err, security.ErrUnsafeURL)
+
+		webhook, err := webhooks.CreateMailboxWebhook(t.Context(), "mb-guest", CreateMailboxWebhookInput{URL: "https://hooks.example.com/a"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{string(domain.WebhookEventMailReceived)}, webhook.Events)
+		assert.Empty(t, webhook.UserID)
+		assert.NotEmpty(t, webhook.Secret)
+		_, err = webhooks.CreateMailboxWebhook(t.Context(), "mb-guest", CreateMailboxWebhookInput{URL: "https://hooks.example.com/b", Secret: "my-own-secret-value"})
+		require.NoError(t, err)
+		_, err = webhooks.CreateMailboxWebhook(t.Context(), "mb-guest", CreateMailboxWebhookInput{URL: "https://hooks.example.com/c"})
+		assert.ErrorIs(t, err, ErrMailboxWebhookLimit)
+
+		listed, err := webhooks.ListMailboxWebhooks(t.Context(), "mb-guest")
+		require.NoError(t, err)
+		assert.Len(t, listed, 2)
+		assert.ErrorIs(t, webhooks.DeleteMailboxWebhook(t.Context(), "mb-user", webhook.ID), ErrWebhookNotFound, "不能删除其他邮箱的 Webhook")
+		require.NoError(t, webhooks.DeleteMailboxWebhook(t.Context(), "mb-guest", webhook.ID))
+	})
+
+	t.Run("收信时投递到邮箱 Webhook", func(t *testing.T) {
+		store, webhooks, recorder, url := setup(t)
+		messages := NewMessageService(store)
+		messages.SetMailReceivedNotifier(webhooks)
+
+		_, err := webhooks.CreateMailboxWebhook(t.Context(), "mb-guest", CreateMailboxWebhookInput{URL: url + "/guest"})
+		require.NoError(t, err)
+		_, err = messages.Create(t.Context(), CreateMessageInput{MailboxID: "mb-guest", From: "a@example.com", To: "g@temp.mail", Subject: "hi"})
+		require.NoError(t, err)
+		_, err = messages.Create(t.Context(), CreateMessageInput{MailboxID: "mb-guest", Subject: "quarantined", Quarantined: true})
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool { return len(recorder.get("/guest")) == 1 }, 2*time.Second, 10*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, []string{"mail.received"}, recorder.get("/guest"), "隔离区邮件不触发")
+	})
+
+	t.Run("用户 Webhook 和邮箱 Webhook 各收到一次", func(t *testing.T) {
+		store, webhooks, recorder, url := setup(t)
+		messages := NewMessageService(store)
+		messages.SetMailReceivedNotifier(webhooks)
+
+		_, err := webhooks.CreateWebhook(t.Context(), CreateWebhookInput{UserID: "user-1", URL: url + "/user", Events: []string{"mail.received"}})
+		require.NoError(t, err)
+		_, err = webhooks.CreateMailboxWebhook(t.Context(), "mb-user", CreateMailboxWebhookInput{URL: url + "/mailbox"})
+		require.NoError(t, err)
+
+		_, err = messages.Create(t.Context(), CreateMessageInput{MailboxID: "mb-user", Subject: "hello"})
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool {
+			return len(recorder.get("/user")) == 1 && len(recorder.get("/mailbox")) == 1
+		}, 2*time.Second, 10*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		assert.Len(t, recorder.get("/user"), 1)
+		assert.Len(t, recorder.get("/mailbox"), 1)
+
+		owned, err := webhooks.ListWebhooks(t.Context(), "user-1")
+		require.NoError(t, err)
+		assert.Len(t, owned, 1, "用户的 Webhook 列表不含邮箱 Webhook")
+	})
+
+	t.Run("邮箱过期时发出 mailbox.expired 并删除 Webhook", func(t *testing.T) {
+		store, webhooks, recorder, url := setup(t)
+		mailboxes := NewMailboxService(store, store, &config.Config{})
+		mailboxes.SetExpiryNotifier(webhooks)
+
+		webhook, err := webhooks.CreateMailboxWebhook(t.Context(), "mb-guest", CreateMailboxWebhookInput{
+			URL: url + "/expiry", Events: []string{"mail.received", "mailbox.expired"},
+		})
+		require.NoError(t, err)
+		// 直接改写过期时间（内存存储返回的是内部指针）
+		stored, err := store.GetMailbox(t.Context(), "mb-guest")
+		require.NoError(t, err)
+		past := time.Now().Add(-time.Minute)
+		stored.ExpiresAt = &past
+
+		count, err := mailboxes.DeleteExpired(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		assert.Eventually(t, func() bool { return len(recorder.get("/expiry")) == 1 }, 2*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"mailbox.expired"}, recorder.get("/expiry"))
+		_, err = store.GetWebhook(t.Context(), webhook.ID)
+		assert.Error(t, err)
+		listed, err := store.ListWebhooksByMailbox(t.Context(), "mb-guest")
+		require.NoError(t, err)
+		assert.Empty(t, listed)
+	})
+
+	t.Run("失败后按访客重试计划重试", func(t *testing.T) {
+		_, webhooks, _, _ := setup(t)
+		webhooks.SetGuestPolicy(fixedGuestPolicy{MaxPerMailbox: 3, RetrySchedule: []string{"30s"}})
+		guest := &domain.Webhook{OwnerType: domain.WebhookOwnerMailbox, OwnerID: "mb-guest"}
+
+		next := webhooks.nextRetry(guest, 1)
+		require.NotNil(t, next)
+		assert.WithinDuration(t, time.Now().Add(30*time.Second), *next, time.Second)
+		assert.Nil(t, webhooks.nextRetry(guest, 2))
+		assert.NotNil(t, webhooks.nextRetry(&domain.Webhook{UserID: "user-1"}, 2), "用户 Webhook 仍使用默认计划")
+	})
+}
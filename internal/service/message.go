@@ -1,33 +1,109 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/htmltext"
+	"tempmail/backend/internal/otp"
+	"tempmail/backend/internal/redact"
+	"tempmail/backend/internal/security"
 	"tempmail/backend/internal/storage"
+	"tempmail/backend/internal/translate"
+)
+
+var (
+	// ErrMessageRawUnavailable 邮件没有可下载的原始内容
+	ErrMessageRawUnavailable = errors.New("message raw content unavailable")
+	// ErrInvalidMessageSort 邮件列表排序参数无效
+	ErrInvalidMessageSort = errors.New("invalid message sort")
+	// ErrInvalidMessagePage 邮件列表分页参数无效
+	ErrInvalidMessagePage = errors.New("invalid message page")
+)
+
+const (
+	// DefaultMessagePageSize 邮件列表默认每页数量
+	DefaultMessagePageSize = 50
+	// MaxMessagePageSize 邮件列表每页最多数量
+	MaxMessagePageSize = 200
 )
 
 // FilesystemStore 文件系统存储接口
 type FilesystemStore interface {
 	SaveMessageRaw(mailboxID, messageID string, rawContent []byte) (string, error)
+	SaveMessageRawFile(mailboxID, messageID, srcPath string) (string, error)
 	SaveMessageMetadata(mailboxID, messageID string, message *domain.Message) (string, error)
 	SaveAttachment(mailboxID, messageID, attachmentID string, attachment *domain.Attachment) (string, error)
 	GetMessageRaw(mailboxID, messageID string) ([]byte, error)
 	GetMessageMetadata(mailboxID, messageID string) (*domain.Message, error)
 	GetAttachment(mailboxID, messageID, attachmentID string) (*domain.Attachment, error)
+	DeleteMessage(mailboxID, messageID string) error
+	DeleteMailbox(mailboxID string) error
+}
+
+// NewMailPublisher 新邮件入库事件（列表可见后调用；WebSocket 推送经 Hub 的跨实例总线，不在此发布）
+type NewMailPublisher interface {
+	PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error
+}
+
+// MailReceivedNotifier 新邮件入库通知（由 WebhookService 实现，只在入库的实例上触发一次）
+type MailReceivedNotifier interface {
+	NotifyMailReceived(message *domain.Message)
+}
+
+// MailReceivedNotifiers 把新邮件入库通知依次转发给多个接收方
+type MailReceivedNotifiers []MailReceivedNotifier
+
+// NotifyMailReceived 实现 MailReceivedNotifier
+func (n MailReceivedNotifiers) NotifyMailReceived(message *domain.Message) {
+	for _, notifier := range n {
+		notifier.NotifyMailReceived(message)
+	}
+}
+
+// MessageDeletedNotifier 通过 API 删除邮件的通知（由 WebhookService 实现，触发 message.deleted）
+//
+// messageID 为空表示清空邮箱，count 为删除数量。
+type MessageDeletedNotifier interface {
+	NotifyMessageDeleted(mailboxID, messageID string, count int)
 }
 
 // MessageService 封装邮件处理逻辑。
 type MessageService struct {
-	repo    storage.MessageRepository
-	fsStore FilesystemStore // 文件系统存储（可选）
+	repo        storage.MessageRepository
+	fsStore     FilesystemStore               // 文件系统存储（可选）
+	translator  translate.Translator          // 翻译服务（可选）
+	translateMu sync.Mutex                    // 保护译文缓存
+	publisher   NewMailPublisher              // 新邮件入库事件（可选）
+	notifier    MailReceivedNotifier          // mail.received Webhook（可选）
+	outbox      *OutboxDispatcher             // 事务性发件箱（可选，启用后新邮件通知由分发器投递）
+	sendMu      sync.Mutex                    // 保护 outbound 及其配额占用
+	outbound    *outbound                     // 发信中继（可选）
+	index       storage.SearchIndexRepository // 全文索引（可选）
+	mailboxes   storage.MailboxRepository     // 删除后查询邮箱统计（与 updates 一起设置）
+	updates     MailboxUpdateNotifier         // 删除后的 mailbox_update 通知（可选）
+	deleted     MessageDeletedNotifier        // message.deleted Webhook（可选）
+	quota       QuotaExceededNotifier         // 发信配额用尽通知（可选）
+	safeHTML    redact.SafeHTMLOptions        // HTML 安全渲染选项
+	now         func() time.Time
 }
 
 // NewMessageService 创建邮件业务服务。
 func NewMessageService(repo storage.MessageRepository) *MessageService {
-	return &MessageService{repo: repo}
+	return &MessageService{repo: repo, now: time.Now}
+}
+
+// SetClock 设置入库时间来源（回放工具用固定时钟保证输出可复现）
+func (s *MessageService) SetClock(now func() time.Time) {
+	s.now = now
 }
 
 // SetFilesystemStore 设置文件系统存储
@@ -35,6 +111,63 @@ func (s *MessageService) SetFilesystemStore(fsStore FilesystemStore) {
 	s.fsStore = fsStore
 }
 
+// SetNewMailPublisher 设置新邮件入库事件
+func (s *MessageService) SetNewMailPublisher(publisher NewMailPublisher) {
+	s.publisher = publisher
+}
+
+// SetSearchIndex 设置全文索引，新邮件入库后写入索引文档
+func (s *MessageService) SetSearchIndex(index storage.SearchIndexRepository) {
+	s.index = index
+}
+
+// SetMailReceivedNotifier 设置新邮件入库通知
+func (s *MessageService) SetMailReceivedNotifier(notifier MailReceivedNotifier) {
+	s.notifier = notifier
+}
+
+// SetOutbox 启用事务性发件箱：新邮件事件与邮件在同一事务写入，入库流程结束后由分发器投递
+//
+// 已设置的新邮件事件和入库通知注册为分发器的目标（须在 SetNewMailPublisher、SetMailReceivedNotifier 之后调用），
+// Create、CreateBatch 不再直接调用它们。
+func (s *MessageService) SetOutbox(outbox *OutboxDispatcher) {
+	if s.publisher != nil {
+		publisher := s.publisher
+		outbox.Register(OutboxTargetPubSub, func(ctx context.Context, message *domain.Message) error {
+			return publisher.PublishNewMail(ctx, message.MailboxID, message)
+		})
+	}
+	if s.notifier != nil {
+		notifier := s.notifier
+		outbox.Register(OutboxTargetWebhook, func(ctx context.Context, message *domain.Message) error {
+			notifier.NotifyMailReceived(message)
+			return nil
+		})
+	}
+	s.outbox = outbox
+}
+
+// OutboxEnabled 是否启用了事务性发件箱（启用后调用方不应再自行推送新邮件通知）
+func (s *MessageService) OutboxEnabled() bool {
+	return s.outbox != nil
+}
+
+// SetMessageDeletedNotifier 设置通过 API 删除邮件后的通知
+func (s *MessageService) SetMessageDeletedNotifier(notifier MessageDeletedNotifier) {
+	s.deleted = notifier
+}
+
+// SetQuotaNotifier 设置发信配额用尽通知
+func (s *MessageService) SetQuotaNotifier(notifier QuotaExceededNotifier) {
+	s.quota = notifier
+}
+
+// SetMailboxUpdateNotifier 设置通过 API 删除邮件后的 mailbox_update 通知
+func (s *MessageService) SetMailboxUpdateNotifier(mailboxes storage.MailboxRepository, notifier MailboxUpdateNotifier) {
+	s.mailboxes = mailboxes
+	s.updates = notifier
+}
+
 // CreateMessageInput 定义创建邮件的输入。
 type CreateMessageInput struct {
 	MailboxID   string
@@ -44,17 +177,149 @@ type CreateMessageInput struct {
 	Text        string
 	HTML        string
 	Raw         string
+	RawFile     string // 已落盘的原始邮件临时文件（流式入库时代替 Raw）
+	RawSize     int64  // RawFile 的大小
 	IsRead      bool
 	Received    time.Time
-	Attachments []*domain.Attachment // 附件列表
+	Attachments []*domain.Attachment // 附件列表（大附件只有 SHA256，内容已暂存为 blob）
+	SpamScore   float64              // 垃圾邮件评分（未评分时为 0）
+	SpamAction  string
+	SpamSymbols []string
+	IsSpam      bool // 评分超过收件人的软阈值
+	Quarantined bool // 投递到隔离区
+	// DKIM 验证结果和签名域名（未验证时为空）
+	DKIMResult string
+	DKIMDomain string
+	// SPF、DMARC 认证结果（未检查时为 nil）
+	AuthResults *domain.AuthenticationResults
+	// 收信时按名单保存的头（键为规范化头名）
+	CapturedHeaders map[string]string
+	// 收件邮箱的到期规则，第一条命中的规则决定邮件的删除时间
+	ExpiryRules []domain.ExpiryRule
 }
 
 // Create 新建一封邮件。
-func (s *MessageService) Create(input CreateMessageInput) (*domain.Message, error) {
-	now := time.Now().UTC()
+//
+// 返回前元数据已提交、列表缓存已失效、内容已落盘，之后才发布新邮件事件；
+// 调用方在 Create 返回后再推送 WebSocket 通知，客户端收到通知时立即拉取列表一定能看到该邮件。
+func (s *MessageService) Create(ctx context.Context, input CreateMessageInput) (*domain.Message, error) {
+	message, err := s.newMessage(input, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先保存元数据到数据库（启用发件箱时事件同一事务写入）
+	var events []*domain.OutboxEvent
+	if s.outbox != nil {
+		events = s.outbox.newEvents([]*domain.Message{message})
+		if err := s.outbox.repo.SaveMessagesWithOutbox(ctx, []*domain.Message{message}, events); err != nil {
+			return nil, err
+		}
+	} else if err := s.repo.SaveMessage(ctx, message); err != nil {
+		return nil, err
+	}
+
+	if s.fsStore != nil {
+		if err := s.persistToFilesystem(message, input); err != nil {
+			return nil, err
+		}
+	}
+	s.indexMessages(ctx, message)
+	s.publish(ctx, events, message)
+
+	return message, nil
+}
+
+// CreateBatch 为多个收件邮箱批量新建邮件（同一封邮件投递给多个收件人时使用）
+//
+// 元数据通过一次批量写入保存（数据库实现为同一事务），任一邮箱失败时全部不保存；
+// 之后逐个落盘，原始邮件临时文件以硬链接共享，附件经 blob 去重。
+func (s *MessageService) CreateBatch(ctx context.Context, inputs []CreateMessageInput) ([]*domain.Message, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	rawCache := make(map[string]string)
+	messages := make([]*domain.Message, 0, len(inputs))
+	for _, input := range inputs {
+		message, err := s.newMessage(input, rawCache)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	var events []*domain.OutboxEvent
+	if s.outbox != nil {
+		events = s.outbox.newEvents(messages)
+		if err := s.outbox.repo.SaveMessagesWithOutbox(ctx, messages, events); err != nil {
+			return nil, err
+		}
+	} else if err := s.repo.SaveMessages(ctx, messages); err != nil {
+		return nil, err
+	}
+
+	if s.fsStore != nil {
+		for i, message := range messages {
+			if err := s.persistToFilesystem(message, inputs[i]); err != nil {
+				return messages[:i], err
+			}
+		}
+	}
+	s.indexMessages(ctx, messages...)
+	s.publish(ctx, events, messages...)
+
+	return messages, nil
+}
+
+// indexMessages 写入全文索引（失败不影响入库，可由 cmd/reindex-search 重建）
+func (s *MessageService) indexMessages(ctx context.Context, messages ...*domain.Message) {
+	if s.index == nil {
+		return
+	}
+	docs := make([]*domain.SearchDocument, len(messages))
+	for i, message := range messages {
+		docs[i] = domain.NewSearchDocument(message)
+	}
+	_ = s.index.IndexMessages(ctx, docs)
+}
+
+// publish 发布新邮件事件并触发 Webhook（失败不影响入库）
+//
+// 启用发件箱时交给分发器投递 events（与 messages 一一对应），失败的由重试任务继续。
+func (s *MessageService) publish(ctx context.Context, events []*domain.OutboxEvent, messages ...*domain.Message) {
+	if s.outbox != nil {
+		s.outbox.dispatch(ctx, events, messages)
+		return
+	}
+	for _, message := range messages {
+		if s.publisher != nil {
+			_ = s.publisher.PublishNewMail(ctx, message.MailboxID, message)
+		}
+		if s.notifier != nil {
+			s.notifier.NotifyMailReceived(message)
+		}
+	}
+}
+
+// newMessage 根据输入构建邮件实体；rawCache 非空时同一临时文件只读入内存一次
+func (s *MessageService) newMessage(input CreateMessageInput, rawCache map[string]string) (*domain.Message, error) {
+	now := s.now().UTC()
 	if input.Received.IsZero() {
 		input.Received = now
 	}
+	hasRaw := input.Raw != "" || input.RawFile != ""
+	classifyAttachments(input.Attachments)
+	size := messageSize(input) // 按原始正文估算，不含生成的纯文本
+
+	// 只有 HTML 正文时由 HTML 生成纯文本，预览、搜索和语言检测都使用它
+	derived := false
+	if strings.TrimSpace(input.Text) == "" && input.HTML != "" {
+		if text := htmltext.Render(input.HTML); text != "" {
+			input.Text = text
+			derived = true
+		}
+	}
 
 	message := &domain.Message{
 		ID:         uuid.NewString(),
@@ -66,39 +331,165 @@ func (s *MessageService) Create(input CreateMessageInput) (*domain.Message, erro
 		CreatedAt:  now,
 		ReceivedAt: input.Received,
 		// 设置文件系统标记
-		HasRaw:  input.Raw != "",
+		HasRaw:  hasRaw,
 		HasHTML: input.HTML != "",
 		HasText: input.Text != "",
+
+		TextDerivedFromHTML: derived,
+		// 正文语言（本地检测，不访问网络）
+		DetectedLanguage: detectLanguage(input.Subject, input.Text, input.HTML),
+		Size:             size,
+		SpamScore:        input.SpamScore,
+		SpamAction:       input.SpamAction,
+		SpamSymbols:      input.SpamSymbols,
+		IsSpam:           input.IsSpam,
+		Quarantined:      input.Quarantined,
+		DKIMResult:       input.DKIMResult,
+		DKIMDomain:       input.DKIMDomain,
+		AuthResults:      input.AuthResults,
+		CapturedHeaders:  input.CapturedHeaders,
+		Verification:     otp.Extract(input.Subject, input.Text, input.HTML),
+		ExpiresAt:        domain.MessageExpiry(input.ExpiryRules, input.From, input.Subject, len(input.Attachments) > 0, now),
 		// 内容字段不存数据库
 		Text:        input.Text,
 		HTML:        input.HTML,
-		Raw:         input.Raw,
 		Attachments: input.Attachments,
 	}
 
-	// 先保存元数据到数据库
-	if err := s.repo.SaveMessage(message); err != nil {
-		return nil, err
-	}
-
-	if s.fsStore != nil {
-		if err := s.persistToFilesystem(message, input); err != nil {
+	// 有文件系统存储时原始邮件只落盘，不随元数据进入数据库或缓存
+	if s.fsStore == nil {
+		if raw, ok := rawCache[input.RawFile]; ok && input.RawFile != "" {
+			message.Raw = raw
+			return message, nil
+		}
+		raw, err := readRaw(input)
+		if err != nil {
 			return nil, err
 		}
+		if rawCache != nil && input.RawFile != "" {
+			rawCache[input.RawFile] = raw
+		}
+		message.Raw = raw
 	}
 
 	return message, nil
 }
 
-// List 列出指定邮箱下的邮件。
-func (s *MessageService) List(mailboxID string) ([]domain.Message, error) {
-	return s.repo.ListMessages(mailboxID)
+// classifyAttachments 为尚未判断实际类型的附件补充类型（SMTP 解析时已判断，这里覆盖 API 创建的邮件）
+func classifyAttachments(attachments []*domain.Attachment) {
+	for _, att := range attachments {
+		if att.DetectedContentType != "" || att.Content == nil {
+			continue
+		}
+		att.DetectedContentType, att.ContentTypeMismatch = security.ClassifyAttachment(att.Filename, att.ContentType, att.Content)
+	}
+}
+
+// messageSize 计算邮件大小：优先取原始邮件长度，否则按正文与附件估算
+func messageSize(input CreateMessageInput) int64 {
+	if input.RawFile != "" {
+		return input.RawSize
+	}
+	if input.Raw != "" {
+		return int64(len(input.Raw))
+	}
+	size := int64(len(input.Text) + len(input.HTML))
+	for _, attachment := range input.Attachments {
+		if attachment != nil {
+			size += attachment.Size
+		}
+	}
+	return size
+}
+
+// readRaw 获取原始邮件内容（没有文件系统存储时才需要读入内存）
+func readRaw(input CreateMessageInput) (string, error) {
+	if input.RawFile == "" {
+		return input.Raw, nil
+	}
+	data, err := os.ReadFile(input.RawFile)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// List 列出指定邮箱下的邮件（不含隔离区）。
+func (s *MessageService) List(ctx context.Context, mailboxID string) ([]domain.Message, error) {
+	return s.listByQuarantine(ctx, mailboxID, false)
+}
+
+// ListQuarantined 列出指定邮箱隔离区中的邮件。
+func (s *MessageService) ListQuarantined(ctx context.Context, mailboxID string) ([]domain.Message, error) {
+	return s.listByQuarantine(ctx, mailboxID, true)
+}
+
+func (s *MessageService) listByQuarantine(ctx context.Context, mailboxID string, quarantined bool) ([]domain.Message, error) {
+	messages, err := s.repo.ListMessages(ctx, mailboxID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.Message, 0, len(messages))
+	for _, message := range messages {
+		if message.Quarantined == quarantined {
+			result = append(result, message)
+		}
+	}
+	return result, nil
+}
+
+// ListPageInput 分页列出邮件的参数
+type ListPageInput struct {
+	MailboxID   string
+	Quarantined bool
+	UnreadOnly  bool
+	IsSpam      *bool
+	Headers     map[string]string
+	Sort        string // receivedAt（默认）、subject 或 from
+	Order       string // desc（默认）或 asc
+	Limit       int    // 默认 50，最多 200
+	Offset      int
+}
+
+// ListPage 按条件分页列出邮件，过滤、排序和分页由存储完成。
+func (s *MessageService) ListPage(ctx context.Context, input ListPageInput) (*domain.MessagePage, error) {
+	query := domain.MessageListQuery{
+		MailboxID:   input.MailboxID,
+		Quarantined: input.Quarantined,
+		UnreadOnly:  input.UnreadOnly,
+		IsSpam:      input.IsSpam,
+		Headers:     input.Headers,
+		Sort:        input.Sort,
+		Limit:       input.Limit,
+		Offset:      input.Offset,
+	}
+	if query.Sort == "" {
+		query.Sort = domain.MessageSortReceivedAt
+	}
+	if !domain.ValidMessageSort(query.Sort) {
+		return nil, ErrInvalidMessageSort
+	}
+	switch input.Order {
+	case "", "desc":
+	case "asc":
+		query.Ascending = true
+	default:
+		return nil, ErrInvalidMessageSort
+	}
+	if query.Limit < 0 || query.Offset < 0 {
+		return nil, ErrInvalidMessagePage
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultMessagePageSize
+	}
+	query.Limit = min(query.Limit, MaxMessagePageSize)
+	return s.repo.ListMessagesPage(ctx, query)
 }
 
 // Get 获取单封邮件详情。
-func (s *MessageService) Get(mailboxID, messageID string) (*domain.Message, error) {
+func (s *MessageService) Get(ctx context.Context, mailboxID, messageID string) (*domain.Message, error) {
 	// 从数据库获取元数据
-	message, err := s.repo.GetMessage(mailboxID, messageID)
+	message, err := s.repo.GetMessage(ctx, mailboxID, messageID)
 	if err != nil {
 		return nil, err
 	}
@@ -120,6 +511,9 @@ func (s *MessageService) Get(mailboxID, messageID string) (*domain.Message, erro
 			if message.HasHTML {
 				message.HTML = metadata.HTML
 			}
+			if len(metadata.TranslatedBodies) > 0 {
+				message.TranslatedBodies = metadata.TranslatedBodies
+			}
 		}
 
 		// 加载附件内容
@@ -133,15 +527,72 @@ func (s *MessageService) Get(mailboxID, messageID string) (*domain.Message, erro
 	return message, nil
 }
 
+// GetRaw 获取邮件入库时保存的原始内容（未经改写的 RFC 822 字节）
+//
+// 邮件没有保存原始内容或原始文件丢失时返回 ErrMessageRawUnavailable。
+func (s *MessageService) GetRaw(ctx context.Context, mailboxID, messageID string) ([]byte, error) {
+	message, err := s.repo.GetMessage(ctx, mailboxID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !message.HasRaw {
+		return nil, ErrMessageRawUnavailable
+	}
+	if s.fsStore == nil {
+		if message.Raw == "" {
+			return nil, ErrMessageRawUnavailable
+		}
+		return []byte(message.Raw), nil
+	}
+	raw, err := s.fsStore.GetMessageRaw(mailboxID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMessageRawUnavailable, err)
+	}
+	return raw, nil
+}
+
+// BackfillText 为只有 HTML 正文的历史邮件生成纯文本，返回生成的纯文本（不需要回填或取不到 HTML 时为空）
+//
+// 有文件系统存储时纯文本写入元数据文件；调用方随后通过存储层标记 HasText。
+func (s *MessageService) BackfillText(ctx context.Context, message *domain.Message) (string, error) {
+	if !message.HasHTML || message.HasText {
+		return "", nil
+	}
+
+	html := message.HTML
+	var metadata *domain.Message
+	if s.fsStore != nil {
+		loaded, err := s.fsStore.GetMessageMetadata(message.MailboxID, message.ID)
+		if err != nil {
+			return "", nil // 与 Get 一致：元数据文件缺失时没有正文可用
+		}
+		metadata, html = loaded, loaded.HTML
+	}
+
+	text := htmltext.Render(html)
+	if text == "" {
+		return "", nil
+	}
+	if metadata != nil {
+		metadata.Text = text
+		metadata.HasText = true
+		metadata.TextDerivedFromHTML = true
+		if _, err := s.fsStore.SaveMessageMetadata(message.MailboxID, message.ID, metadata); err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
+
 // MarkRead 将邮件标记为已读。
-func (s *MessageService) MarkRead(mailboxID, messageID string) error {
-	return s.repo.MarkMessageRead(mailboxID, messageID)
+func (s *MessageService) MarkRead(ctx context.Context, mailboxID, messageID string) error {
+	return s.repo.MarkMessageRead(ctx, mailboxID, messageID)
 }
 
 // GetAttachment 获取邮件附件。
-func (s *MessageService) GetAttachment(mailboxID, messageID, attachmentID string) (*domain.Attachment, error) {
+func (s *MessageService) GetAttachment(ctx context.Context, mailboxID, messageID, attachmentID string) (*domain.Attachment, error) {
 	// 先验证邮件是否存在
-	message, err := s.repo.GetMessage(mailboxID, messageID)
+	message, err := s.repo.GetMessage(ctx, mailboxID, messageID)
 	if err != nil {
 		return nil, err
 	}
@@ -162,20 +613,77 @@ func (s *MessageService) GetAttachment(mailboxID, messageID, attachmentID string
 }
 
 // Delete 删除指定邮件。
-func (s *MessageService) Delete(mailboxID, messageID string) error {
-	return s.repo.DeleteMessage(mailboxID, messageID)
+func (s *MessageService) Delete(ctx context.Context, mailboxID, messageID string) error {
+	if err := s.repo.DeleteMessage(ctx, mailboxID, messageID); err != nil {
+		return err
+	}
+
+	// 删除文件并释放附件 blob 引用（失败时由过期清理兜底）
+	if s.fsStore != nil {
+		_ = s.fsStore.DeleteMessage(mailboxID, messageID)
+	}
+	return nil
 }
 
 // ClearAll 清空邮箱中的所有邮件，返回删除数量。
-func (s *MessageService) ClearAll(mailboxID string) (int, error) {
-	return s.repo.DeleteAllMessages(mailboxID)
+func (s *MessageService) ClearAll(ctx context.Context, mailboxID string) (int, error) {
+	count, err := s.repo.DeleteAllMessages(ctx, mailboxID)
+	if err != nil {
+		return count, err
+	}
+
+	if s.fsStore != nil {
+		_ = s.fsStore.DeleteMailbox(mailboxID)
+	}
+	return count, nil
+}
+
+// Remove 删除指定邮件并推送 mailbox_update（用户通过 API 删除时使用）
+func (s *MessageService) Remove(ctx context.Context, mailboxID, messageID string) error {
+	if err := s.Delete(ctx, mailboxID, messageID); err != nil {
+		return err
+	}
+	s.notifyMailboxUpdate(ctx, mailboxID)
+	if s.deleted != nil {
+		s.deleted.NotifyMessageDeleted(mailboxID, messageID, 1)
+	}
+	return nil
+}
+
+// RemoveAll 清空邮箱并推送 mailbox_update，返回删除数量（用户通过 API 清空时使用）
+func (s *MessageService) RemoveAll(ctx context.Context, mailboxID string) (int, error) {
+	count, err := s.ClearAll(ctx, mailboxID)
+	if err != nil {
+		return count, err
+	}
+	if count > 0 {
+		s.notifyMailboxUpdate(ctx, mailboxID)
+		if s.deleted != nil {
+			s.deleted.NotifyMessageDeleted(mailboxID, "", count)
+		}
+	}
+	return count, nil
+}
+
+// notifyMailboxUpdate 推送邮箱最新的统计（查询失败时不推送）
+func (s *MessageService) notifyMailboxUpdate(ctx context.Context, mailboxID string) {
+	if s.updates == nil || s.mailboxes == nil {
+		return
+	}
+	if mailbox, err := s.mailboxes.GetMailbox(ctx, mailboxID); err == nil {
+		s.updates.NotifyMailboxUpdate(mailbox)
+	}
 }
 
 func (s *MessageService) persistToFilesystem(message *domain.Message, input CreateMessageInput) error {
 	mailboxID := input.MailboxID
 	messageID := message.ID
 
-	if input.Raw != "" {
+	if input.RawFile != "" {
+		if _, err := s.fsStore.SaveMessageRawFile(mailboxID, messageID, input.RawFile); err != nil {
+			return err
+		}
+	} else if input.Raw != "" {
 		if _, err := s.fsStore.SaveMessageRaw(mailboxID, messageID, []byte(input.Raw)); err != nil {
 			return err
 		}
@@ -207,6 +715,11 @@ func (s *MessageService) persistToFilesystem(message *domain.Message, input Crea
 			ContentType: att.ContentType,
 			Size:        att.Size,
 			StoragePath: path,
+
+			DetectedContentType: att.DetectedContentType,
+			ContentTypeMismatch: att.ContentTypeMismatch,
+			Infected:            att.Infected,
+			Threat:              att.Threat,
 		})
 	}
 
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+var (
+	ErrTooManyExpiryRules = errors.New("too many expiry rules")
+	ErrExpiryRulePattern  = errors.New("invalid expiry rule pattern")
+	ErrExpiryRuleTTL      = errors.New("expiry rule ttl out of range")
+)
+
+// MinExpiryRuleTTL 到期规则的最短时长
+const MinExpiryRuleTTL = time.Minute
+
+// MailboxUpdateNotifier 邮箱统计变化通知（由 WebSocket Hub 实现）
+type MailboxUpdateNotifier interface {
+	NotifyMailboxUpdate(mailbox *domain.Mailbox)
+}
+
+// MessageExpiryService 邮件到期规则：管理邮箱的规则，并定时删除按规则到期的邮件
+//
+// 规则只在入库时计算一次删除时间（Message.ExpiresAt），修改规则不影响已入库的邮件，
+// 除非显式要求重新计算。没有命中规则的邮件随邮箱一起过期。
+type MessageExpiryService struct {
+	mailboxes  storage.MailboxRepository
+	messages   *MessageService
+	cfg        *config.Config
+	notifier   MailboxUpdateNotifier // mailbox_update 通知（可选）
+	defaultTTL DefaultTTLSource      // 邮箱默认有效期（可选，未设置时使用启动配置）
+}
+
+// DefaultTTLSource 提供邮箱默认有效期（MailboxService 实现，随系统配置变化）
+type DefaultTTLSource interface {
+	DefaultTTL() time.Duration
+}
+
+// NewMessageExpiryService 创建邮件到期规则服务，时钟与 messages 共用
+func NewMessageExpiryService(mailboxes storage.MailboxRepository, messages *MessageService, cfg *config.Config) *MessageExpiryService {
+	return &MessageExpiryService{mailboxes: mailboxes, messages: messages, cfg: cfg}
+}
+
+// SetMailboxUpdateNotifier 设置邮箱统计变化通知
+func (s *MessageExpiryService) SetMailboxUpdateNotifier(notifier MailboxUpdateNotifier) {
+	s.notifier = notifier
+}
+
+// SetDefaultTTLSource 设置邮箱默认有效期的来源，使系统配置的修改即时生效
+func (s *MessageExpiryService) SetDefaultTTLSource(source DefaultTTLSource) {
+	s.defaultTTL = source
+}
+
+// Rules 返回邮箱的到期规则
+func (s *MessageExpiryService) Rules(ctx context.Context, mailboxID string) ([]domain.ExpiryRule, error) {
+	mailbox, err := s.mailboxes.GetMailbox(ctx, mailboxID)
+	if err != nil {
+		return nil, err
+	}
+	return mailbox.ExpiryRules, nil
+}
+
+// UpdateRules 替换邮箱的到期规则，返回更新后的邮箱和重新计算删除时间的邮件数
+//
+// 时长须在 1 分钟到邮箱有效期之间，正则必须能编译。reapply 为 true 时按新规则
+// 重新计算邮箱中现有邮件的删除时间（不再命中任何规则的邮件恢复随邮箱过期）。
+func (s *MessageExpiryService) UpdateRules(ctx context.Context, mailboxID string, rules []domain.ExpiryRule, reapply bool) (*domain.Mailbox, int, error) {
+	mailbox, err := s.mailboxes.GetMailbox(ctx, mailboxID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.validate(mailbox, rules); err != nil {
+		return nil, 0, err
+	}
+
+	if len(rules) == 0 {
+		rules = nil
+	}
+	mailbox.ExpiryRules = rules
+	if err := s.mailboxes.SaveMailbox(ctx, mailbox); err != nil {
+		return nil, 0, err
+	}
+	if !reapply {
+		return mailbox, 0, nil
+	}
+
+	restamped, err := s.reapply(ctx, mailbox)
+	return mailbox, restamped, err
+}
+
+// validate 校验规则数量、时长和正则
+func (s *MessageExpiryService) validate(mailbox *domain.Mailbox, rules []domain.ExpiryRule) error {
+	if len(rules) > domain.MaxExpiryRules {
+		return ErrTooManyExpiryRules
+	}
+	retention := s.retention(mailbox)
+	for _, rule := range rules {
+		if rule.TTL < MinExpiryRuleTTL || (retention > 0 && rule.TTL > retention) {
+			return ErrExpiryRuleTTL
+		}
+		if _, _, err := rule.Compile(); err != nil {
+			return ErrExpiryRulePattern
+		}
+	}
+	return nil
+}
+
+// retention 邮箱的有效期：有过期时间时按创建到过期计算，否则为系统默认有效期（0 表示不限）
+func (s *MessageExpiryService) retention(mailbox *domain.Mailbox) time.Duration {
+	if mailbox.ExpiresAt != nil {
+		return mailbox.ExpiresAt.Sub(mailbox.CreatedAt)
+	}
+	if s.defaultTTL != nil {
+		return s.defaultTTL.DefaultTTL()
+	}
+	return s.cfg.Mailbox.DefaultTTL
+}
+
+// reapply 按邮箱当前规则重新计算已入库邮件的删除时间，返回删除时间发生变化的邮件数
+func (s *MessageExpiryService) reapply(ctx context.Context, mailbox *domain.Mailbox) (int, error) {
+	messages, err := s.messages.repo.ListMessages(ctx, mailbox.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for i := range messages {
+		message := &messages[i]
+		expiresAt := domain.MessageExpiry(mailbox.ExpiryRules, message.From, message.Subject, s.hasAttachments(message), message.CreatedAt)
+		if sameExpiry(message.ExpiresAt, expiresAt) {
+			continue
+		}
+		if err := s.messages.repo.SetMessageExpiry(ctx, mailbox.ID, message.ID, expiresAt); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// hasAttachments 邮件是否带附件（数据库存储只保存元数据，附件列表在文件系统元数据中）
+func (s *MessageExpiryService) hasAttachments(message *domain.Message) bool {
+	if len(message.Attachments) > 0 {
+		return true
+	}
+	if s.messages.fsStore == nil {
+		return false
+	}
+	metadata, err := s.messages.fsStore.GetMessageMetadata(message.MailboxID, message.ID)
+	return err == nil && len(metadata.Attachments) > 0
+}
+
+func sameExpiry(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// SweepExpired 删除按规则到期的邮件，返回删除数量
+//
+// 邮件文件一并删除（失败时由过期清理兜底），每个受影响的邮箱只推送一次 mailbox_update。
+func (s *MessageExpiryService) SweepExpired(ctx context.Context) (int, error) {
+	// 部分邮箱删除失败时，已删除的邮箱照常清理和通知
+	expired, err := s.messages.repo.DeleteExpiredMessages(ctx, s.messages.now())
+
+	deleted := 0
+	for _, batch := range expired {
+		deleted += len(batch.MessageIDs)
+		if s.messages.fsStore != nil {
+			for _, messageID := range batch.MessageIDs {
+				_ = s.messages.fsStore.DeleteMessage(batch.MailboxID, messageID)
+			}
+		}
+		if s.notifier == nil {
+			continue
+		}
+		if mailbox, getErr := s.mailboxes.GetMailbox(ctx, batch.MailboxID); getErr == nil {
+			s.notifier.NotifyMailboxUpdate(mailbox)
+		}
+	}
+	return deleted, err
+}
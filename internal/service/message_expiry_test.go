@@ -0,0 +1,225 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// recordingUpdates 记录 mailbox_update 通知
+type recordingUpdates struct {
+	mailboxes []domain.Mailbox
+}
+
+func (r *recordingUpdates) NotifyMailboxUpdate(mailbox *domain.Mailbox) {
+	r.mailboxes = append(r.mailboxes, *mailbox)
+}
+
+type expiryFixture struct {
+	store    *memory.Store
+	messages *MessageService
+	expiry   *MessageExpiryService
+	updates  *recordingUpdates
+	now      time.Time
+}
+
+func newExpiryFixture(t *testing.T) *expiryFixture {
+	t.Helper()
+	store := memory.NewStore(0)
+	f := &expiryFixture{
+		store:    store,
+		messages: NewMessageService(store),
+		updates:  &recordingUpdates{},
+		now:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	f.messages.SetClock(func() time.Time { return f.now })
+	f.expiry = NewMessageExpiryService(store, f.messages, &config.Config{Mailbox: config.MailboxConfig{DefaultTTL: 24 * time.Hour}})
+	f.expiry.SetMailboxUpdateNotifier(f.updates)
+	return f
+}
+
+func (f *expiryFixture) addMailbox(t *testing.T, id string, rules ...domain.ExpiryRule) {
+	t.Helper()
+	require.NoError(t, f.store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: id, Address: id + "@example.com", CreatedAt: f.now,
+	}))
+	if len(rules) > 0 {
+		_, _, err := f.expiry.UpdateRules(t.Context(), id, rules, false)
+		require.NoError(t, err)
+	}
+}
+
+// deliver 按入库路径投递一封邮件（规则取自邮箱）
+func (f *expiryFixture) deliver(t *testing.T, mailboxID, from, subject string, attachments ...*domain.Attachment) *domain.Message {
+	t.Helper()
+	rules, err := f.expiry.Rules(t.Context(), mailboxID)
+	require.NoError(t, err)
+	message, err := f.messages.Create(t.Context(), CreateMessageInput{
+		MailboxID: mailboxID, From: from, Subject: subject, Text: "body",
+		Attachments: attachments, ExpiryRules: rules,
+	})
+	require.NoError(t, err)
+	return message
+}
+
+func boolPtr(v bool) *bool { return &v }
+
+func TestMessageExpiryRules(t *testing.T) {
+	otp := domain.ExpiryRule{SubjectPattern: `verification code|验证码`, TTL: 15 * time.Minute}
+	newsletter := domain.ExpiryRule{FromPattern: `@news\.example\.com$`, TTL: 12 * time.Hour}
+	invoices := domain.ExpiryRule{HasAttachment: boolPtr(true), TTL: time.Hour}
+
+	t.Run("按顺序匹配，第一条命中的规则生效", func(t *testing.T) {
+		f := newExpiryFixture(t)
+		f.addMailbox(t, "mb", otp, newsletter, invoices)
+
+		code := f.deliver(t, "mb", "no-reply@news.example.com", "Your Verification Code is 123456")
+		require.NotNil(t, code.ExpiresAt)
+		assert.Equal(t, f.now.Add(15*time.Minute), *code.ExpiresAt, "主题规则排在前面，优先于发件人规则")
+
+		digest := f.deliver(t, "mb", "digest@NEWS.example.com", "Weekly digest")
+		require.NotNil(t, digest.ExpiresAt)
+		assert.Equal(t, f.now.Add(12*time.Hour), *digest.ExpiresAt)
+
+		invoice := f.deliver(t, "mb", "billing@shop.example", "Invoice", &domain.Attachment{ID: "a1", Filename: "invoice.pdf", Content: []byte("%PDF")})
+		require.NotNil(t, invoice.ExpiresAt)
+		assert.Equal(t, f.now.Add(time.Hour), *invoice.ExpiresAt)
+
+		plain := f.deliver(t, "mb", "friend@example.org", "Hello")
+		assert.Nil(t, plain.ExpiresAt, "没有命中规则时随邮箱过期")
+	})
+
+	t.Run("校验数量、时长和正则", func(t *testing.T) {
+		f := newExpiryFixture(t)
+		f.addMailbox(t, "mb")
+
+		tooMany := make([]domain.ExpiryRule, domain.MaxExpiryRules+1)
+		for i := range tooMany {
+			tooMany[i] = otp
+		}
+		_, _, err := f.expiry.UpdateRules(t.Context(), "mb", tooMany, false)
+		assert.ErrorIs(t, err, ErrTooManyExpiryRules)
+
+		_, _, err = f.expiry.UpdateRules(t.Context(), "mb", []domain.ExpiryRule{{TTL: 30 * time.Second}}, false)
+		assert.ErrorIs(t, err, ErrExpiryRuleTTL)
+		_, _, err = f.expiry.UpdateRules(t.Context(), "mb", []domain.ExpiryRule{{TTL: 25 * time.Hour}}, false)
+		assert.ErrorIs(t, err, ErrExpiryRuleTTL, "不能超过邮箱有效期")
+		_, _, err = f.expiry.UpdateRules(t.Context(), "mb", []domain.ExpiryRule{{SubjectPattern: "(", TTL: time.Hour}}, false)
+		assert.ErrorIs(t, err, ErrExpiryRulePattern)
+
+		_, _, err = f.expiry.UpdateRules(t.Context(), "mb", []domain.ExpiryRule{otp, newsletter}, false)
+		require.NoError(t, err)
+		rules, err := f.expiry.Rules(t.Context(), "mb")
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ExpiryRule{otp, newsletter}, rules)
+	})
+
+	t.Run("有过期时间的邮箱按自身有效期限制时长", func(t *testing.T) {
+		f := newExpiryFixture(t)
+		created := time.Now().UTC() // 内存存储按真实时间清理过期邮箱
+		expires := created.Add(2 * time.Hour)
+		require.NoError(t, f.store.SaveMailbox(t.Context(), &domain.Mailbox{
+			ID: "short", Address: "short@example.com", CreatedAt: created, ExpiresAt: &expires,
+		}))
+
+		_, _, err := f.expiry.UpdateRules(t.Context(), "short", []domain.ExpiryRule{{TTL: 3 * time.Hour}}, false)
+		assert.ErrorIs(t, err, ErrExpiryRuleTTL)
+		_, _, err = f.expiry.UpdateRules(t.Context(), "short", []domain.ExpiryRule{{TTL: 2 * time.Hour}}, false)
+		assert.NoError(t, err)
+	})
+
+	t.Run("修改规则不影响已入库的邮件，reapply 时重新计算", func(t *testing.T) {
+		f := newExpiryFixture(t)
+		f.addMailbox(t, "mb", otp)
+		code := f.deliver(t, "mb", "a@example.com", "验证码 654321")
+		plain := f.deliver(t, "mb", "friend@example.org", "Hello")
+
+		slower := domain.ExpiryRule{SubjectPattern: `验证码`, TTL: 2 * time.Hour}
+		catchAll := domain.ExpiryRule{TTL: 6 * time.Hour}
+		_, reapplied, err := f.expiry.UpdateRules(t.Context(), "mb", []domain.ExpiryRule{slower, catchAll}, false)
+		require.NoError(t, err)
+		assert.Zero(t, reapplied)
+
+		stored, err := f.store.GetMessage(t.Context(), "mb", code.ID)
+		require.NoError(t, err)
+		assert.Equal(t, f.now.Add(15*time.Minute), *stored.ExpiresAt)
+		stored, err = f.store.GetMessage(t.Context(), "mb", plain.ID)
+		require.NoError(t, err)
+		assert.Nil(t, stored.ExpiresAt)
+
+		_, reapplied, err = f.expiry.UpdateRules(t.Context(), "mb", []domain.ExpiryRule{slower, catchAll}, true)
+		require.NoError(t, err)
+		assert.Equal(t, 2, reapplied)
+		stored, err = f.store.GetMessage(t.Context(), "mb", code.ID)
+		require.NoError(t, err)
+		assert.Equal(t, f.now.Add(2*time.Hour), *stored.ExpiresAt, "按入库时间重新计算")
+		stored, err = f.store.GetMessage(t.Context(), "mb", plain.ID)
+		require.NoError(t, err)
+		assert.Equal(t, f.now.Add(6*time.Hour), *stored.ExpiresAt)
+
+		// 清除规则并重新计算：全部恢复随邮箱过期
+		_, reapplied, err = f.expiry.UpdateRules(t.Context(), "mb", nil, true)
+		require.NoError(t, err)
+		assert.Equal(t, 2, reapplied)
+		stored, err = f.store.GetMessage(t.Context(), "mb", code.ID)
+		require.NoError(t, err)
+		assert.Nil(t, stored.ExpiresAt)
+	})
+
+	t.Run("按时钟删除到期邮件并修正统计，每个邮箱通知一次", func(t *testing.T) {
+		f := newExpiryFixture(t)
+		f.addMailbox(t, "a", otp)
+		f.addMailbox(t, "b", otp)
+		f.addMailbox(t, "c", otp)
+
+		read := f.deliver(t, "a", "x@example.com", "verification code 1")
+		require.NoError(t, f.messages.MarkRead(t.Context(), "a", read.ID))
+		f.deliver(t, "a", "x@example.com", "verification code 2")
+		keep := f.deliver(t, "a", "x@example.com", "Hello")
+		f.deliver(t, "b", "x@example.com", "verification code 3")
+		f.deliver(t, "c", "x@example.com", "Hello")
+
+		// 未到期：不删除、不通知
+		f.now = f.now.Add(14 * time.Minute)
+		count, err := f.expiry.SweepExpired(t.Context())
+		require.NoError(t, err)
+		assert.Zero(t, count)
+		assert.Empty(t, f.updates.mailboxes)
+
+		f.now = f.now.Add(time.Minute)
+		count, err = f.expiry.SweepExpired(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+
+		mailboxA, err := f.store.GetMailbox(t.Context(), "a")
+		require.NoError(t, err)
+		assert.Equal(t, 1, mailboxA.TotalCount)
+		assert.Equal(t, 1, mailboxA.Unread)
+		remaining, err := f.store.ListMessages(t.Context(), "a")
+		require.NoError(t, err)
+		require.Len(t, remaining, 1)
+		assert.Equal(t, keep.ID, remaining[0].ID)
+
+		mailboxB, err := f.store.GetMailbox(t.Context(), "b")
+		require.NoError(t, err)
+		assert.Zero(t, mailboxB.TotalCount)
+		assert.Zero(t, mailboxB.Unread)
+
+		require.Len(t, f.updates.mailboxes, 2, "只通知受影响的邮箱，每个一次")
+		assert.Equal(t, "a", f.updates.mailboxes[0].ID)
+		assert.Equal(t, 1, f.updates.mailboxes[0].TotalCount)
+		assert.Equal(t, "b", f.updates.mailboxes[1].ID)
+
+		// 再次清理没有可删除的邮件
+		count, err = f.expiry.SweepExpired(t.Context())
+		require.NoError(t, err)
+		assert.Zero(t, count)
+		assert.Len(t, f.updates.mailboxes, 2)
+	})
+}
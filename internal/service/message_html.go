@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"tempmail/backend/internal/redact"
+)
+
+// ErrMessageHTMLUnavailable 邮件没有 HTML 正文
+var ErrMessageHTMLUnavailable = errors.New("message has no html body")
+
+// SetSafeHTMLOptions 设置安全渲染选项（远程图片的处理方式，默认拦截）
+func (s *MessageService) SetSafeHTMLOptions(opts redact.SafeHTMLOptions) {
+	s.safeHTML = opts
+}
+
+// RemoteImages 安全渲染时远程图片的处理方式（block、proxy 或 allow）
+func (s *MessageService) RemoteImages() string {
+	switch {
+	case s.safeHTML.RemoteImages == redact.RemoteImagesAllow:
+		return redact.RemoteImagesAllow
+	case s.safeHTML.RemoteImages == redact.RemoteImagesProxy && s.safeHTML.ProxyURL != nil:
+		return redact.RemoteImagesProxy
+	}
+	return redact.RemoteImagesBlock
+}
+
+// HTML 返回邮件的原始 HTML 正文
+func (s *MessageService) HTML(ctx context.Context, mailboxID, messageID string) (string, error) {
+	message, err := s.repo.GetMessage(ctx, mailboxID, messageID)
+	if err != nil {
+		return "", err
+	}
+	body := message.HTML
+	if body == "" && message.HasHTML && s.fsStore != nil {
+		if metadata, err := s.fsStore.GetMessageMetadata(mailboxID, messageID); err == nil {
+			body = metadata.HTML
+		}
+	}
+	if body == "" {
+		return "", ErrMessageHTMLUnavailable
+	}
+	return body, nil
+}
+
+// SafeHTML 返回邮件 HTML 正文的安全渲染版本
+//
+// 移除脚本、表单、事件属性和跟踪像素，按配置拦截或代理远程图片，结果可以直接在页面中展示。
+func (s *MessageService) SafeHTML(ctx context.Context, mailboxID, messageID string) (*redact.SafeHTMLResult, error) {
+	body, err := s.HTML(ctx, mailboxID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	return redact.SafeHTML(body, s.safeHTML)
+}
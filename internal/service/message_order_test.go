@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+	"tempmail/backend/internal/storage/hybrid"
+	"tempmail/backend/internal/storage/memory"
+	"tempmail/backend/internal/storage/postgres"
+)
+
+// orderingStore 测试用的邮件存储（需要能创建邮箱）
+type orderingStore interface {
+	storage.MessageRepository
+	SaveMailbox(ctx context.Context, mailbox *domain.Mailbox) error
+}
+
+// listOnPublish 收到新邮件事件时立即拉取列表，记录被引用的邮件是否可见
+type listOnPublish struct {
+	repo    storage.MessageRepository
+	mu      sync.Mutex
+	missing []string
+	seqs    []int64
+}
+
+func (p *listOnPublish) PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error {
+	messages, err := p.repo.ListMessages(context.Background(), mailboxID)
+	found := false
+	for _, msg := range messages {
+		if msg.ID == message.ID {
+			found = true
+			break
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil || !found {
+		p.missing = append(p.missing, message.ID)
+	}
+	p.seqs = append(p.seqs, message.Seq)
+	return nil
+}
+
+func TestMessageService_Ordering(t *testing.T) {
+	stores := map[string]func(t *testing.T) orderingStore{
+		"内存": func(t *testing.T) orderingStore { return memory.NewStore(time.Hour) },
+		"SQLite": func(t *testing.T) orderingStore {
+			store, err := postgres.NewSQLiteStore(filepath.Join(t.TempDir(), "tempmail.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+		"混合存储": func(t *testing.T) orderingStore {
+			store, err := hybrid.NewStandaloneStore("sqlite", filepath.Join(t.TempDir(), "tempmail.db"), memory.NewStore(time.Hour))
+			require.NoError(t, err)
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name+"：并发入库的序号严格递增，列表按序号排列，通知时邮件已可见", func(t *testing.T) {
+			store := newStore(t)
+			expires := time.Now().Add(time.Hour)
+			require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+				ID: "mb-1", Address: "bulk@temp.mail", LocalPart: "bulk", Domain: "temp.mail",
+				Token: "tok-1", CreatedAt: time.Now(), ExpiresAt: &expires,
+			}))
+
+			messages := NewMessageService(store)
+			publisher := &listOnPublish{repo: store}
+			messages.SetNewMailPublisher(publisher)
+
+			const count = 50
+			var wg sync.WaitGroup
+			errs := make(chan error, count)
+			for i := 0; i < count; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, err := messages.Create(t.Context(), CreateMessageInput{
+						MailboxID: "mb-1", From: "ci@example.com", To: "bulk@temp.mail",
+						Subject: fmt.Sprintf("msg %d", i), Text: "body",
+					})
+					errs <- err
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				require.NoError(t, err)
+			}
+
+			listed, err := messages.List(t.Context(), "mb-1")
+			require.NoError(t, err)
+			require.Len(t, listed, count)
+			seen := make(map[int64]bool, count)
+			for i, msg := range listed {
+				assert.Equal(t, int64(count-i), msg.Seq, "列表按序号倒序")
+				assert.False(t, seen[msg.Seq], "序号不重复")
+				seen[msg.Seq] = true
+			}
+
+			assert.Empty(t, publisher.missing)
+			assert.Len(t, publisher.seqs, count)
+		})
+
+		t.Run(name+"：批量入库按输入顺序连续分配序号", func(t *testing.T) {
+			store := newStore(t)
+			expires := time.Now().Add(time.Hour)
+			for _, id := range []string{"mb-1", "mb-2"} {
+				require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+					ID: id, Address: id + "@temp.mail", LocalPart: id, Domain: "temp.mail", Token: id, CreatedAt: time.Now(), ExpiresAt: &expires,
+				}))
+			}
+			messages := NewMessageService(store)
+			_, err := messages.Create(t.Context(), CreateMessageInput{MailboxID: "mb-1", Subject: "first"})
+			require.NoError(t, err)
+
+			batch, err := messages.CreateBatch(t.Context(), []CreateMessageInput{
+				{MailboxID: "mb-1", Subject: "a"},
+				{MailboxID: "mb-2", Subject: "b"},
+				{MailboxID: "mb-1", Subject: "c"},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{2, 1, 3}, []int64{batch[0].Seq, batch[1].Seq, batch[2].Seq})
+
+			listed, err := messages.List(t.Context(), "mb-1")
+			require.NoError(t, err)
+			require.Len(t, listed, 3)
+			assert.Equal(t, []string{"c", "a", "first"}, []string{listed[0].Subject, listed[1].Subject, listed[2].Subject})
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/otp"
+)
+
+// ErrOTPNotFound 没有找到包含验证码或验证链接的邮件
+var ErrOTPNotFound = errors.New("no verification code found")
+
+// latestOTPScan 查找最新验证码时最多检查的邮件数（按接收时间倒序）
+const latestOTPScan = 50
+
+// MessageOTP 邮件中提取的验证码和验证链接
+type MessageOTP struct {
+	MessageID  string    `json:"messageId"`
+	From       string    `json:"from"`
+	Subject    string    `json:"subject"`
+	ReceivedAt time.Time `json:"receivedAt"`
+	domain.Verification
+}
+
+func newMessageOTP(message *domain.Message, v *domain.Verification) *MessageOTP {
+	result := &MessageOTP{
+		MessageID:  message.ID,
+		From:       message.From,
+		Subject:    message.Subject,
+		ReceivedAt: message.ReceivedAt,
+	}
+	if v != nil {
+		result.Verification = *v
+	}
+	return result
+}
+
+// OTP 返回邮件中的验证码和验证链接（都没有时字段为空）
+//
+// 入库时已提取的直接返回；此前入库的邮件读取正文重新提取。
+func (s *MessageService) OTP(ctx context.Context, mailboxID, messageID string) (*MessageOTP, error) {
+	message, err := s.repo.GetMessage(ctx, mailboxID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Verification != nil {
+		return newMessageOTP(message, message.Verification), nil
+	}
+	full, err := s.Get(ctx, mailboxID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	return newMessageOTP(full, otp.Extract(full.Subject, full.Text, full.HTML)), nil
+}
+
+// LatestOTP 返回邮箱中最新一封带验证码或验证链接的邮件（不含隔离区）
+//
+// since 非零时只查找此后接收的邮件，便于在触发发送后轮询；只检查最近 latestOTPScan 封邮件。
+func (s *MessageService) LatestOTP(ctx context.Context, mailboxID string, since time.Time) (*MessageOTP, error) {
+	page, err := s.repo.ListMessagesPage(ctx, domain.MessageListQuery{
+		MailboxID: mailboxID,
+		Sort:      domain.MessageSortReceivedAt,
+		Limit:     latestOTPScan,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range page.Messages {
+		message := &page.Messages[i]
+		if !since.IsZero() && message.ReceivedAt.Before(since) {
+			break
+		}
+		if !message.Verification.Empty() {
+			return newMessageOTP(message, message.Verification), nil
+		}
+	}
+	return nil, ErrOTPNotFound
+}
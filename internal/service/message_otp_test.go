@@ -0,0 +1,71 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestMessageService_OTP(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "qa@temp.mail", LocalPart: "qa", Domain: "temp.mail", CreatedAt: time.Now(),
+	}))
+	messages := NewMessageService(store)
+	base := time.Now().Add(-time.Hour).UTC()
+	deliver := func(subject, text string, received time.Time) *domain.Message {
+		msg, err := messages.Create(t.Context(), CreateMessageInput{
+			MailboxID: "mb-1", From: "no-reply@example.com", Subject: subject, Text: text, Received: received,
+		})
+		require.NoError(t, err)
+		return msg
+	}
+
+	first := deliver("Your code", "Your verification code is 111222.", base)
+	deliver("Newsletter", "Nothing to see here.", base.Add(time.Minute))
+
+	t.Run("入库时提取", func(t *testing.T) {
+		require.NotNil(t, first.Verification)
+		assert.Equal(t, "111222", first.Verification.Code)
+
+		result, err := messages.OTP(t.Context(), "mb-1", first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, result.MessageID)
+		assert.Equal(t, "111222", result.Code)
+	})
+
+	t.Run("此前入库的邮件重新提取", func(t *testing.T) {
+		legacy := &domain.Message{
+			ID: "legacy", MailboxID: "mb-1", Subject: "登录验证", Text: "您的验证码是 908172", HasText: true,
+			CreatedAt: base, ReceivedAt: base,
+		}
+		require.NoError(t, store.SaveMessage(t.Context(), legacy))
+
+		result, err := messages.OTP(t.Context(), "mb-1", "legacy")
+		require.NoError(t, err)
+		assert.Equal(t, "908172", result.Code)
+
+		_, err = messages.OTP(t.Context(), "mb-1", "missing")
+		assert.ErrorIs(t, err, memory.ErrMessageNotFound)
+	})
+
+	t.Run("最新的验证码", func(t *testing.T) {
+		latest, err := messages.LatestOTP(t.Context(), "mb-1", time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, latest.MessageID, "跳过没有验证码的邮件")
+
+		second := deliver("Sign in", "Your login code: 333444", base.Add(2*time.Minute))
+		latest, err = messages.LatestOTP(t.Context(), "mb-1", base.Add(90*time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, second.ID, latest.MessageID)
+		assert.Equal(t, "333444", latest.Code)
+
+		_, err = messages.LatestOTP(t.Context(), "mb-1", base.Add(3*time.Minute))
+		assert.ErrorIs(t, err, ErrOTPNotFound)
+	})
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/filesystem"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestMessageService_GetRaw(t *testing.T) {
+	const raw = "From: a@example.com\r\nSubject: hi\r\n\r\nbody\r\n"
+
+	setup := func(t *testing.T, withFS bool) *MessageService {
+		store := memory.NewStore(24 * time.Hour)
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+			ID: "mb-1", Address: "qa@temp.mail", LocalPart: "qa", Domain: "temp.mail", CreatedAt: time.Now(),
+		}))
+		messages := NewMessageService(store)
+		if withFS {
+			fs, err := filesystem.NewStore(t.TempDir())
+			require.NoError(t, err)
+			messages.SetFilesystemStore(fs)
+		}
+		return messages
+	}
+
+	for _, withFS := range []bool{true, false} {
+		name := "内存存储"
+		if withFS {
+			name = "文件系统存储"
+		}
+		t.Run(name, func(t *testing.T) {
+			messages := setup(t, withFS)
+			msg, err := messages.Create(t.Context(), CreateMessageInput{MailboxID: "mb-1", From: "a@example.com", Subject: "hi", Raw: raw, Text: "body"})
+			require.NoError(t, err)
+
+			got, err := messages.GetRaw(t.Context(), "mb-1", msg.ID)
+			require.NoError(t, err)
+			assert.Equal(t, raw, string(got))
+		})
+	}
+
+	t.Run("没有原始内容", func(t *testing.T) {
+		messages := setup(t, true)
+		msg, err := messages.Create(t.Context(), CreateMessageInput{MailboxID: "mb-1", From: "a@example.com", Subject: "api", Text: "body"})
+		require.NoError(t, err)
+
+		_, err = messages.GetRaw(t.Context(), "mb-1", msg.ID)
+		assert.ErrorIs(t, err, ErrMessageRawUnavailable)
+	})
+
+	t.Run("邮件不存在", func(t *testing.T) {
+		messages := setup(t, false)
+		_, err := messages.GetRaw(t.Context(), "mb-1", "missing")
+		assert.ErrorIs(t, err, memory.ErrMessageNotFound)
+	})
+}
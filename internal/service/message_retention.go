@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"tempmail/backend/internal/domain"
+)
+
+// RetentionPolicySource 按用户等级的邮件保留时长来源（由 ConfigService 实现）
+type RetentionPolicySource interface {
+	MessageRetention() domain.RetentionPolicyConfig
+}
+
+// MessageRetentionService 按邮箱所属用户等级删除超过保留时长的邮件
+//
+// 保留时长在系统配置中设置，管理员修改后下一轮清理即按新时长执行；邮箱本身不受影响。
+// 组织邮箱按创建者的等级计算，游客邮箱使用 Guest 时长。
+type MessageRetentionService struct {
+	store    domain.Store
+	messages *MessageService
+	policy   RetentionPolicySource
+	notifier MailboxUpdateNotifier // mailbox_update 通知（可选）
+}
+
+// NewMessageRetentionService 创建邮件保留清理服务，时钟与 messages 共用
+func NewMessageRetentionService(store domain.Store, messages *MessageService, policy RetentionPolicySource) *MessageRetentionService {
+	return &MessageRetentionService{store: store, messages: messages, policy: policy}
+}
+
+// SetMailboxUpdateNotifier 设置邮箱统计变化通知
+func (s *MessageRetentionService) SetMailboxUpdateNotifier(notifier MailboxUpdateNotifier) {
+	s.notifier = notifier
+}
+
+// SweepExpired 删除超过所属用户等级保留时长的邮件（数据库记录和邮件文件），返回删除数量
+//
+// 查不到所属用户的邮箱本轮跳过，避免误按游客时长删除。每个受影响的邮箱只推送一次 mailbox_update。
+func (s *MessageRetentionService) SweepExpired(ctx context.Context) (int, error) {
+	policy := s.policy.MessageRetention()
+	if !policy.Enabled() {
+		return 0, nil
+	}
+	now := s.messages.now()
+
+	tiers := make(map[string]*domain.UserTier) // 本轮已查询的用户等级
+	deleted := 0
+	var errs []error
+	for _, mb := range s.store.ListMailboxes(ctx) {
+		var tier *domain.UserTier
+		if mb.UserID != nil {
+			cached, ok := tiers[*mb.UserID]
+			if !ok {
+				if user, err := s.store.GetUserByID(ctx, *mb.UserID); err == nil {
+					cached = &user.Tier
+				}
+				tiers[*mb.UserID] = cached
+			}
+			if cached == nil {
+				continue
+			}
+			tier = cached
+		}
+		retention := policy.For(tier)
+		if retention <= 0 {
+			continue
+		}
+
+		messages, err := s.messages.repo.ListMessages(ctx, mb.ID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cutoff := now.Add(-retention)
+		removed := 0
+		for _, msg := range messages {
+			if !msg.ReceivedAt.Before(cutoff) {
+				continue
+			}
+			if err := s.messages.Delete(ctx, mb.ID, msg.ID); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			removed++
+		}
+		deleted += removed
+		if removed > 0 && s.notifier != nil {
+			if mailbox, err := s.store.GetMailbox(ctx, mb.ID); err == nil {
+				s.notifier.NotifyMailboxUpdate(mailbox)
+			}
+		}
+	}
+	return deleted, errors.Join(errs...)
+}
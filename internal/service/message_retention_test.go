@@ -0,0 +1,76 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// staticRetention 固定的保留时长
+type staticRetention domain.RetentionPolicyConfig
+
+func (p *staticRetention) MessageRetention() domain.RetentionPolicyConfig {
+	return domain.RetentionPolicyConfig(*p)
+}
+
+func TestMessageRetentionService_SweepExpired(t *testing.T) {
+	store := memory.NewStore(0)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	messages := NewMessageService(store)
+	messages.SetClock(func() time.Time { return now })
+	policy := &staticRetention{}
+	updates := &recordingUpdates{}
+	retention := NewMessageRetentionService(store, messages, policy)
+	retention.SetMailboxUpdateNotifier(updates)
+
+	freeUser, proUser := "user-free", "user-pro"
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: freeUser, Email: "free@example.com", Username: "free", Tier: domain.TierFree}))
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: proUser, Email: "pro@example.com", Username: "pro", Tier: domain.TierPro}))
+	for id, userID := range map[string]*string{"mb-guest": nil, "mb-free": &freeUser, "mb-pro": &proUser} {
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: id, Address: id + "@example.com", UserID: userID, CreatedAt: now}))
+		for i, age := range []time.Duration{30 * time.Minute, 2 * time.Hour, 48 * time.Hour} {
+			require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+				ID: id + "-" + string(rune('a'+i)), MailboxID: id, ReceivedAt: now.Add(-age), CreatedAt: now.Add(-age),
+			}))
+		}
+	}
+	remaining := func(mailboxID string) int {
+		list, err := store.ListMessages(t.Context(), mailboxID)
+		require.NoError(t, err)
+		return len(list)
+	}
+
+	t.Run("未设置保留时长时不删除", func(t *testing.T) {
+		deleted, err := retention.SweepExpired(t.Context())
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+	})
+
+	t.Run("按等级删除超过保留时长的邮件", func(t *testing.T) {
+		*policy = staticRetention{Guest: "1h", Tiers: map[domain.UserTier]string{domain.TierFree: "1h", domain.TierPro: "168h"}}
+
+		deleted, err := retention.SweepExpired(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 4, deleted)
+		assert.Equal(t, 1, remaining("mb-guest"))
+		assert.Equal(t, 1, remaining("mb-free"))
+		assert.Equal(t, 3, remaining("mb-pro"))
+		assert.Len(t, updates.mailboxes, 2)
+	})
+
+	t.Run("未设置的等级不限制", func(t *testing.T) {
+		*policy = staticRetention{Tiers: map[domain.UserTier]string{domain.TierFree: "1h"}}
+		now = now.Add(24 * time.Hour)
+
+		deleted, err := retention.SweepExpired(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		assert.Equal(t, 1, remaining("mb-guest"))
+		assert.Equal(t, 3, remaining("mb-pro"))
+	})
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/mailfmt"
+	"tempmail/backend/internal/storage"
+)
+
+var (
+	ErrSendingDisabled       = errors.New("outbound sending is not configured")
+	ErrSendRequiresAccount   = errors.New("guest mailboxes cannot send mail")
+	ErrSendRecipientsInvalid = errors.New("recipients are missing or invalid")
+	ErrSendTooManyRecipients = errors.New("too many recipients")
+	ErrSendBodyEmpty         = errors.New("message body is empty")
+	ErrSendBodyTooLarge      = errors.New("message body is too large")
+	ErrSendReplyNotFound     = errors.New("message to reply to not found")
+	// ErrSendRelayFailed 中继拒绝或不可达（包装原始错误）
+	ErrSendRelayFailed = errors.New("outbound relay failed")
+)
+
+const (
+	// SendQuotaWindow 发信配额的统计窗口（滚动）
+	SendQuotaWindow = 24 * time.Hour
+	// MaxSendBodySize 纯文本和 HTML 正文合计的最大字节数
+	MaxSendBodySize = 1 << 20
+)
+
+// 发信配额的范围
+const (
+	SendQuotaMailbox = "mailbox"
+	SendQuotaUser    = "user"
+)
+
+// SendQuotaError 发信配额用尽
+type SendQuotaError struct {
+	Scope string    // SendQuotaMailbox 或 SendQuotaUser
+	Limit int       // 窗口内的上限
+	Reset time.Time // 窗口内最早一封移出窗口的时间
+}
+
+func (e *SendQuotaError) Error() string {
+	return fmt.Sprintf("%s send quota of %d messages per %s exceeded", e.Scope, e.Limit, SendQuotaWindow)
+}
+
+// event 转换为 quota.exceeded 事件数据
+func (e *SendQuotaError) event(mailboxID, userID string) QuotaExceededData {
+	reset := e.Reset
+	data := QuotaExceededData{Scope: QuotaScopeMailboxSend, Limit: e.Limit, MailboxID: mailboxID, ResetAt: &reset}
+	if e.Scope == SendQuotaUser {
+		data.Scope, data.UserID = QuotaScopeUserSend, userID
+	}
+	return data
+}
+
+// MailSender 外发邮件（由 smtp.Relay 实现）
+type MailSender interface {
+	Send(ctx context.Context, from string, to []string, message []byte) error
+}
+
+// SendOptions 发信限制
+type SendOptions struct {
+	MaxRecipients     int    // 收件人（含抄送）上限，0 表示不限
+	MailboxDailyLimit int    // 每个邮箱在 SendQuotaWindow 内的发信上限，0 表示不限
+	UserDailyLimit    int    // 每个用户（名下所有邮箱合计）的发信上限，0 表示不限
+	AllowGuests       bool   // 允许游客邮箱发信
+	Domain            string // Message-ID 的域名部分（为空时使用发件邮箱的域名）
+}
+
+// SendMessageInput 发信参数
+type SendMessageInput struct {
+	To      []string
+	Cc      []string
+	Subject string
+	Text    string
+	HTML    string
+	// ReplyToMessageID 回复的收件邮件：未指定收件人时回复原发件人，未指定主题时使用 "Re: 原主题"，
+	// 并设置 In-Reply-To/References
+	ReplyToMessageID string
+}
+
+// outbound 发信依赖和进行中的配额占用
+type outbound struct {
+	sender  MailSender
+	sent    storage.SentMessageRepository
+	options SendOptions
+	pending map[string]int // 已通过配额检查、尚未写入已发送记录的数量（按配额键）
+}
+
+// SetOutbound 启用发信：经 sender 投递，发出的邮件记录到 sent（“已发送”文件夹）并据此统计配额
+func (s *MessageService) SetOutbound(sender MailSender, sent storage.SentMessageRepository, options SendOptions) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	s.outbound = &outbound{sender: sender, sent: sent, options: options, pending: make(map[string]int)}
+}
+
+// SendingEnabled 是否已配置发信中继
+func (s *MessageService) SendingEnabled() bool {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	return s.outbound != nil
+}
+
+// Send 以邮箱地址为发件人经中继发出一封邮件，并记录到已发送文件夹
+//
+// 配额按已发送记录在滚动窗口内统计，同时计入本实例正在发送的邮件；中继失败不占用配额。
+func (s *MessageService) Send(ctx context.Context, mailbox *domain.Mailbox, input SendMessageInput) (*domain.SentMessage, error) {
+	s.sendMu.Lock()
+	out := s.outbound
+	s.sendMu.Unlock()
+	if out == nil {
+		return nil, ErrSendingDisabled
+	}
+	userID := ""
+	if mailbox.UserID != nil {
+		userID = *mailbox.UserID
+	}
+	if userID == "" && !out.options.AllowGuests {
+		return nil, ErrSendRequiresAccount
+	}
+
+	sent := &domain.SentMessage{
+		ID:        uuid.New().String(),
+		MailboxID: mailbox.ID,
+		UserID:    userID,
+		From:      mailbox.Address,
+		Subject:   strings.TrimSpace(input.Subject),
+		Text:      input.Text,
+		HTML:      input.HTML,
+	}
+	var inReplyTo string
+	var references []string
+	if input.ReplyToMessageID != "" {
+		original, err := s.Get(ctx, mailbox.ID, input.ReplyToMessageID)
+		if err != nil || original.Quarantined {
+			return nil, ErrSendReplyNotFound
+		}
+		sent.ReplyToMessageID = original.ID
+		if len(input.To) == 0 {
+			input.To = []string{replyAddress(original)}
+		}
+		if sent.Subject == "" {
+			sent.Subject = replySubject(original.Subject)
+		}
+		inReplyTo, references = replyHeaders(original)
+	}
+
+	to, err := parseRecipients(input.To)
+	if err != nil || len(to) == 0 {
+		return nil, ErrSendRecipientsInvalid
+	}
+	cc, err := parseRecipients(input.Cc)
+	if err != nil {
+		return nil, ErrSendRecipientsInvalid
+	}
+	if limit := out.options.MaxRecipients; limit > 0 && len(to)+len(cc) > limit {
+		return nil, ErrSendTooManyRecipients
+	}
+	if strings.TrimSpace(sent.Text) == "" && strings.TrimSpace(sent.HTML) == "" {
+		return nil, ErrSendBodyEmpty
+	}
+	if len(sent.Text)+len(sent.HTML) > MaxSendBodySize {
+		return nil, ErrSendBodyTooLarge
+	}
+
+	release, err := s.reserveSend(ctx, out, mailbox.ID, userID)
+	if err != nil {
+		var quotaErr *SendQuotaError
+		if errors.As(err, &quotaErr) && s.quota != nil {
+			s.quota.NotifyQuotaExceeded(quotaErr.event(mailbox.ID, userID))
+		}
+		return nil, err
+	}
+	defer release()
+
+	domainName := out.options.Domain
+	if domainName == "" {
+		domainName = mailbox.Domain
+	}
+	sent.InternetMessageID = sent.ID + "@" + domainName
+	sent.SentAt = s.now()
+	var rcpts []string
+	for _, address := range append(append([]*mail.Address{}, to...), cc...) {
+		rcpts = append(rcpts, address.Address)
+		if len(sent.To) < len(to) {
+			sent.To = append(sent.To, address.String())
+		} else {
+			sent.Cc = append(sent.Cc, address.String())
+		}
+	}
+	raw := mailfmt.Compose(mailfmt.Outgoing{
+		From:       mailbox.Address,
+		To:         sent.To,
+		Cc:         sent.Cc,
+		Subject:    sent.Subject,
+		Text:       sent.Text,
+		HTML:       sent.HTML,
+		MessageID:  sent.InternetMessageID,
+		InReplyTo:  inReplyTo,
+		References: references,
+		Date:       sent.SentAt,
+	})
+	sent.Size = int64(len(raw))
+
+	if err := out.sender.Send(ctx, mailbox.Address, rcpts, raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSendRelayFailed, err)
+	}
+	if err := out.sent.SaveSentMessage(ctx, sent); err != nil {
+		return nil, fmt.Errorf("record sent message: %w", err)
+	}
+	return sent, nil
+}
+
+// ListSent 列出邮箱已发送的邮件（新邮件在前）
+func (s *MessageService) ListSent(ctx context.Context, mailboxID string) ([]*domain.SentMessage, error) {
+	s.sendMu.Lock()
+	out := s.outbound
+	s.sendMu.Unlock()
+	if out == nil {
+		return []*domain.SentMessage{}, nil
+	}
+	return out.sent.ListSentMessages(ctx, mailboxID)
+}
+
+// reserveSend 检查邮箱和用户配额并占用一个名额，返回的 release 在记录写入（或发送失败）后释放占用
+func (s *MessageService) reserveSend(ctx context.Context, out *outbound, mailboxID, userID string) (func(), error) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	now := s.now()
+	since := now.Add(-SendQuotaWindow)
+	checks := []struct {
+		scope, key string
+		limit      int
+		filter     domain.SentMessageFilter
+	}{
+		{SendQuotaMailbox, "mailbox:" + mailboxID, out.options.MailboxDailyLimit, domain.SentMessageFilter{MailboxID: mailboxID, Since: since}},
+		{SendQuotaUser, "user:" + userID, out.options.UserDailyLimit, domain.SentMessageFilter{UserID: userID, Since: since}},
+	}
+	var keys []string
+	for _, check := range checks {
+		if check.limit <= 0 || (check.scope == SendQuotaUser && userID == "") {
+			continue
+		}
+		usage, err := out.sent.CountSentMessages(ctx, check.filter)
+		if err != nil {
+			return nil, err
+		}
+		if int(usage.Count)+out.pending[check.key] >= check.limit {
+			reset := now.Add(SendQuotaWindow)
+			if usage.Count > 0 {
+				reset = usage.OldestAt.Add(SendQuotaWindow)
+			}
+			return nil, &SendQuotaError{Scope: check.scope, Limit: check.limit, Reset: reset}
+		}
+		keys = append(keys, check.key)
+	}
+
+	for _, key := range keys {
+		out.pending[key]++
+	}
+	return func() {
+		s.sendMu.Lock()
+		defer s.sendMu.Unlock()
+		for _, key := range keys {
+			if out.pending[key]--; out.pending[key] <= 0 {
+				delete(out.pending, key)
+			}
+		}
+	}, nil
+}
+
+// parseRecipients 解析并去重收件人地址
+func parseRecipients(values []string) ([]*mail.Address, error) {
+	seen := make(map[string]bool, len(values))
+	var addresses []*mail.Address
+	for _, value := range values {
+		address, err := mail.ParseAddress(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(address.Address)
+		if !seen[key] {
+			seen[key] = true
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
+
+// replyAddress 回复地址：原邮件的 Reply-To，没有时为发件人
+func replyAddress(original *domain.Message) string {
+	if header, err := mail.ReadMessage(strings.NewReader(original.Raw)); err == nil {
+		if replyTo := header.Header.Get("Reply-To"); replyTo != "" {
+			return replyTo
+		}
+	}
+	return original.From
+}
+
+func replySubject(subject string) string {
+	subject = strings.TrimSpace(subject)
+	if len(subject) >= 3 && strings.EqualFold(subject[:3], "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+// replyHeaders 取原邮件的 Message-ID 作为 In-Reply-To，并在其 References 后追加
+func replyHeaders(original *domain.Message) (string, []string) {
+	header, err := mail.ReadMessage(strings.NewReader(original.Raw))
+	if err != nil {
+		return "", nil
+	}
+	messageID := strings.Trim(strings.TrimSpace(header.Header.Get("Message-Id")), "<>")
+	if messageID == "" {
+		return "", nil
+	}
+	var references []string
+	for _, field := range strings.Fields(header.Header.Get("References")) {
+		if id := strings.Trim(field, "<>"); id != "" {
+			references = append(references, id)
+		}
+	}
+	return messageID, append(references, messageID)
+}
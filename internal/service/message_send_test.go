@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+type sentMail struct {
+	from    string
+	to      []string
+	message string
+}
+
+type fakeMailSender struct {
+	sent []sentMail
+	err  error
+}
+
+func (f *fakeMailSender) Send(_ context.Context, from string, to []string, message []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, sentMail{from: from, to: to, message: string(message)})
+	return nil
+}
+
+type sendFixture struct {
+	store     *memory.Store
+	mailboxes *MailboxService
+	messages  *MessageService
+	sender    *fakeMailSender
+	mailbox   *domain.Mailbox
+	now       time.Time
+}
+
+func newSendFixture(t *testing.T, options SendOptions) *sendFixture {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.example"}}}
+	f := &sendFixture{
+		store:     store,
+		mailboxes: NewMailboxService(store, store, cfg),
+		messages:  NewMessageService(store),
+		sender:    &fakeMailSender{},
+		now:       time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	f.messages.SetClock(func() time.Time { return f.now })
+	f.messages.SetOutbound(f.sender, store, options)
+	f.mailbox = f.createMailbox(t, "team", "user-1")
+	return f
+}
+
+func (f *sendFixture) createMailbox(t *testing.T, prefix, userID string) *domain.Mailbox {
+	t.Helper()
+	input := CreateMailboxInput{Prefix: prefix, Domain: "temp.example"}
+	if userID != "" {
+		input.UserID = &userID
+	}
+	mailbox, err := f.mailboxes.Create(t.Context(), input)
+	require.NoError(t, err)
+	return mailbox
+}
+
+func TestMessageService_Send(t *testing.T) {
+	t.Run("经中继发出并记录到已发送", func(t *testing.T) {
+		f := newSendFixture(t, SendOptions{MaxRecipients: 3, Domain: "mx.temp.example"})
+
+		sent, err := f.messages.Send(t.Context(), f.mailbox, SendMessageInput{
+			To: []string{"Alice <alice@example.com>", "ALICE@example.com"}, Cc: []string{"bob@example.com"},
+			Subject: "Hello", Text: "hi there",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{`"Alice" <alice@example.com>`}, sent.To)
+		assert.Equal(t, []string{"<bob@example.com>"}, sent.Cc)
+		assert.Equal(t, "user-1", sent.UserID)
+		assert.True(t, strings.HasSuffix(sent.InternetMessageID, "@mx.temp.example"))
+
+		require.Len(t, f.sender.sent, 1)
+		delivered := f.sender.sent[0]
+		assert.Equal(t, f.mailbox.Address, delivered.from)
+		assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, delivered.to)
+		assert.Contains(t, delivered.message, "Subject: Hello\r\n")
+		assert.Contains(t, delivered.message, "Message-ID: <"+sent.InternetMessageID+">\r\n")
+
+		list, err := f.messages.ListSent(t.Context(), f.mailbox.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, sent.ID, list[0].ID)
+	})
+
+	t.Run("回复收件邮件", func(t *testing.T) {
+		f := newSendFixture(t, SendOptions{})
+		original, err := f.messages.Create(t.Context(), CreateMessageInput{
+			MailboxID: f.mailbox.ID, From: "carol@vendor.example", To: f.mailbox.Address, Subject: "Invoice 42", Text: "pay",
+			Raw: "From: carol@vendor.example\r\nReply-To: billing@vendor.example\r\nMessage-ID: <orig@vendor.example>\r\n" +
+				"References: <root@vendor.example>\r\nSubject: Invoice 42\r\n\r\npay\r\n",
+		})
+		require.NoError(t, err)
+
+		sent, err := f.messages.Send(t.Context(), f.mailbox, SendMessageInput{ReplyToMessageID: original.ID, Text: "paid"})
+		require.NoError(t, err)
+		assert.Equal(t, "Re: Invoice 42", sent.Subject)
+		assert.Equal(t, []string{"<billing@vendor.example>"}, sent.To)
+		assert.Equal(t, original.ID, sent.ReplyToMessageID)
+		message := f.sender.sent[0].message
+		assert.Contains(t, message, "In-Reply-To: <orig@vendor.example>\r\n")
+		assert.Contains(t, message, "References: <root@vendor.example> <orig@vendor.example>\r\n")
+
+		_, err = f.messages.Send(t.Context(), f.mailbox, SendMessageInput{ReplyToMessageID: "missing", Text: "x"})
+		assert.ErrorIs(t, err, ErrSendReplyNotFound)
+	})
+
+	t.Run("参数校验", func(t *testing.T) {
+		f := newSendFixture(t, SendOptions{MaxRecipients: 2})
+		cases := []struct {
+			input SendMessageInput
+			err   error
+		}{
+			{SendMessageInput{Text: "x"}, ErrSendRecipientsInvalid},
+			{SendMessageInput{To: []string{"not an address"}, Text: "x"}, ErrSendRecipientsInvalid},
+			{SendMessageInput{To: []string{"a@example.com", "b@example.com"}, Cc: []string{"c@example.com"}, Text: "x"}, ErrSendTooManyRecipients},
+			{SendMessageInput{To: []string{"a@example.com"}, Text: "  "}, ErrSendBodyEmpty},
+			{SendMessageInput{To: []string{"a@example.com"}, Text: strings.Repeat("x", MaxSendBodySize+1)}, ErrSendBodyTooLarge},
+		}
+		for _, tc := range cases {
+			_, err := f.messages.Send(t.Context(), f.mailbox, tc.input)
+			assert.ErrorIs(t, err, tc.err)
+		}
+		assert.Empty(t, f.sender.sent)
+	})
+
+	t.Run("游客邮箱默认不能发信", func(t *testing.T) {
+		f := newSendFixture(t, SendOptions{})
+		guest := f.createMailbox(t, "guest", "")
+		input := SendMessageInput{To: []string{"a@example.com"}, Text: "x"}
+		_, err := f.messages.Send(t.Context(), guest, input)
+		assert.ErrorIs(t, err, ErrSendRequiresAccount)
+
+		f.messages.SetOutbound(f.sender, f.store, SendOptions{AllowGuests: true})
+		_, err = f.messages.Send(t.Context(), guest, input)
+		assert.NoError(t, err)
+	})
+
+	t.Run("未配置中继", func(t *testing.T) {
+		f := newSendFixture(t, SendOptions{})
+		messages := NewMessageService(f.store)
+		_, err := messages.Send(t.Context(), f.mailbox, SendMessageInput{To: []string{"a@example.com"}, Text: "x"})
+		assert.ErrorIs(t, err, ErrSendingDisabled)
+	})
+
+	t.Run("中继失败不记录也不占用配额", func(t *testing.T) {
+		f := newSendFixture(t, SendOptions{MailboxDailyLimit: 1})
+		f.sender.err = errors.New("550 relay denied")
+		input := SendMessageInput{To: []string{"a@example.com"}, Text: "x"}
+		_, err := f.messages.Send(t.Context(), f.mailbox, input)
+		assert.ErrorIs(t, err, ErrSendRelayFailed)
+
+		f.sender.err = nil
+		_, err = f.messages.Send(t.Context(), f.mailbox, input)
+		assert.NoError(t, err)
+	})
+}
+
+func TestMessageService_SendQuota(t *testing.T) {
+	input := SendMessageInput{To: []string{"a@example.com"}, Text: "x"}
+
+	t.Run("邮箱配额按滚动窗口统计", func(t *testing.T) {
+		f := newSendFixture(t, SendOptions{MailboxDailyLimit: 2})
+		first := f.now
+		for range 2 {
+			_, err := f.messages.Send(t.Context(), f.mailbox, input)
+			require.NoError(t, err)
+			f.now = f.now.Add(time.Hour)
+		}
+
+		_, err := f.messages.Send(t.Context(), f.mailbox, input)
+		var quotaErr *SendQuotaError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, SendQuotaMailbox, quotaErr.Scope)
+		assert.Equal(t, 2, quotaErr.Limit)
+		assert.Equal(t, first.Add(SendQuotaWindow), quotaErr.Reset)
+
+		// 其他邮箱不受影响
+		other := f.createMailbox(t, "other", "user-2")
+		_, err = f.messages.Send(t.Context(), other, input)
+		assert.NoError(t, err)
+
+		f.now = first.Add(SendQuotaWindow)
+		_, err = f.messages.Send(t.Context(), f.mailbox, input)
+		assert.NoError(t, err)
+	})
+
+	t.Run("用户配额合计名下所有邮箱", func(t *testing.T) {
+		f := newSendFixture(t, SendOptions{UserDailyLimit: 2})
+		second := f.createMailbox(t, "second", "user-1")
+		_, err := f.messages.Send(t.Context(), f.mailbox, input)
+		require.NoError(t, err)
+		_, err = f.messages.Send(t.Context(), second, input)
+		require.NoError(t, err)
+
+		_, err = f.messages.Send(t.Context(), second, input)
+		var quotaErr *SendQuotaError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, SendQuotaUser, quotaErr.Scope)
+	})
+}
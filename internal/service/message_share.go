@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/redact"
+	"tempmail/backend/internal/storage"
+)
+
+var (
+	ErrShareExpiryInvalid    = errors.New("share expiry must be between 1 minute and 7 days")
+	ErrShareMessageNotFound  = errors.New("message to share not found")
+	ErrShareNotAllowed       = errors.New("quarantined messages cannot be shared")
+	ErrShareRedactionMissing = errors.New("message has no redacted copy to share")
+	ErrShareNotFound         = errors.New("message share not found")
+	// ErrShareLinkInvalid 签名无效、令牌格式错误或邮件已不存在（不区分，避免探测）
+	ErrShareLinkInvalid = errors.New("share link is invalid")
+	// ErrShareLinkExpired 链接已过期或被撤销
+	ErrShareLinkExpired           = errors.New("share link has expired or been revoked")
+	ErrShareAttachmentsNotAllowed = errors.New("share link does not include attachments")
+)
+
+// 分享链接有效期
+const (
+	DefaultShareTTL = 24 * time.Hour
+	MinShareTTL     = time.Minute
+	MaxShareTTL     = 7 * 24 * time.Hour
+)
+
+// shareKeyLabel HKDF 派生分享签名密钥的标签，与 JWT 签名用途隔开
+const shareKeyLabel = "tempmail message share v1"
+
+// ShareKeySource 分享链接签名密钥的来源（由 JWT Manager 实现）
+//
+// 签名密钥由当前 JWT 密钥派生，轮换 JWT 密钥后此前的分享链接全部失效。
+type ShareKeySource interface {
+	Keys() (jwtpkg.Key, *jwtpkg.Key)
+}
+
+// CreateShareInput 创建分享链接参数
+type CreateShareInput struct {
+	TTL              time.Duration // 为 0 时使用 DefaultShareTTL
+	AllowAttachments bool
+	Redacted         bool // 分享脱敏副本（需先生成脱敏副本）
+}
+
+// MessageShareLink 分享记录及其链接令牌
+type MessageShareLink struct {
+	domain.MessageShare
+	Token string `json:"token,omitempty"` // 撤销或过期后为空
+}
+
+// SharedMessage 通过分享链接查看的邮件（HTML 已清理，不含邮箱的其他信息）
+type SharedMessage struct {
+	From        string             `json:"from"`
+	Subject     string             `json:"subject"`
+	Text        string             `json:"text"`
+	HTML        string             `json:"html"`
+	ReceivedAt  time.Time          `json:"receivedAt"`
+	Redacted    bool               `json:"redacted"`
+	ExpiresAt   time.Time          `json:"expiresAt"`             // 链接过期时间
+	Attachments []PublicAttachment `json:"attachments,omitempty"` // 仅允许下载附件时返回
+}
+
+// shareClaims 分享令牌载荷
+type shareClaims struct {
+	ShareID          string `json:"sid"`
+	MessageID        string `json:"mid,omitempty"` // 整个邮箱的分享为空
+	ExpiresAt        int64  `json:"exp"`
+	AllowAttachments bool   `json:"att,omitempty"`
+	Redacted         bool   `json:"red,omitempty"`
+	Nonce            string `json:"n"`
+}
+
+// MessageShareService 单封邮件或整个邮箱的限时只读分享链接
+//
+// 链接令牌为 HMAC 签名的 URL 安全载荷（分享ID、邮件ID、过期时间、选项和随机 nonce），
+// nonce 同时保存在分享记录中，撤销后令牌不再匹配。访问次数记录在分享记录上。
+type MessageShareService struct {
+	store      storage.Store
+	messages   *MessageService
+	redactions *RedactionService
+	keys       ShareKeySource
+	now        func() time.Time
+}
+
+// NewMessageShareService 创建分享链接服务
+func NewMessageShareService(store storage.Store, messages *MessageService, keys ShareKeySource) *MessageShareService {
+	return &MessageShareService{store: store, messages: messages, keys: keys, now: time.Now}
+}
+
+// SetRedactionService 启用脱敏副本分享（未设置时 redacted 分享返回 ErrShareRedactionMissing）
+func (s *MessageShareService) SetRedactionService(redactions *RedactionService) {
+	s.redactions = redactions
+}
+
+// SetClock 替换时间源（测试用）
+func (s *MessageShareService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Create 为邮件创建分享链接
+func (s *MessageShareService) Create(ctx context.Context, mailboxID, messageID string, input CreateShareInput) (*MessageShareLink, error) {
+	ttl := input.TTL
+	if ttl == 0 {
+		ttl = DefaultShareTTL
+	}
+	if ttl < MinShareTTL || ttl > MaxShareTTL {
+		return nil, ErrShareExpiryInvalid
+	}
+
+	// 各存储实现的“邮件不存在”错误不同，统一视为不存在
+	message, err := s.store.GetMessage(ctx, mailboxID, messageID)
+	if err != nil {
+		return nil, ErrShareMessageNotFound
+	}
+	if message.Quarantined {
+		return nil, ErrShareNotAllowed
+	}
+	if input.Redacted {
+		if s.redactions == nil {
+			return nil, ErrShareRedactionMissing
+		}
+		if _, err := s.redactions.Get(ctx, mailboxID, messageID); err != nil {
+			if errors.Is(err, storage.ErrRedactionNotFound) {
+				return nil, ErrShareRedactionMissing
+			}
+			return nil, err
+		}
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	share := &domain.MessageShare{
+		ID:               uuid.NewString(),
+		MailboxID:        mailboxID,
+		MessageID:        message.ID,
+		Nonce:            base64.RawURLEncoding.EncodeToString(nonce),
+		AllowAttachments: input.AllowAttachments,
+		Redacted:         input.Redacted,
+		ExpiresAt:        now.Add(ttl).Truncate(time.Second),
+		CreatedAt:        now,
+	}
+	if err := s.store.SaveMessageShare(ctx, share); err != nil {
+		return nil, err
+	}
+	return s.link(share)
+}
+
+// List 列出邮箱的分享链接及访问次数（新链接在前，仍有效的链接带令牌）
+func (s *MessageShareService) List(ctx context.Context, mailboxID string) ([]MessageShareLink, error) {
+	shares, err := s.store.ListMessageShares(ctx, mailboxID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]MessageShareLink, 0, len(shares))
+	for _, share := range shares {
+		link, err := s.link(share)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *link)
+	}
+	return result, nil
+}
+
+// Revoke 撤销分享链接（重复撤销不报错）
+func (s *MessageShareService) Revoke(ctx context.Context, mailboxID, shareID string) (*domain.MessageShare, error) {
+	share, err := s.store.GetMessageShare(ctx, shareID)
+	if err != nil {
+		if errors.Is(err, storage.ErrMessageShareNotFound) {
+			return nil, ErrShareNotFound
+		}
+		return nil, err
+	}
+	if share.MailboxID != mailboxID {
+		return nil, ErrShareNotFound
+	}
+	if share.RevokedAt != nil {
+		return share, nil
+	}
+
+	now := s.now()
+	share.RevokedAt = &now
+	if err := s.store.SaveMessageShare(ctx, share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// Open 按链接令牌查看邮件并计入访问次数
+func (s *MessageShareService) Open(ctx context.Context, token string) (*SharedMessage, error) {
+	share, err := s.verify(ctx, token, false)
+	if err != nil {
+		return nil, err
+	}
+	message, err := s.messages.Get(ctx, share.MailboxID, share.MessageID)
+	if err != nil || message.Quarantined {
+		return nil, ErrShareLinkInvalid
+	}
+
+	result, err := s.sharedMessage(ctx, share, message)
+	if err != nil {
+		return nil, err
+	}
+	// 计数失败不影响查看
+	_ = s.store.RecordMessageShareView(ctx, share.ID, s.now())
+	return result, nil
+}
+
+// sharedMessage 按分享选项组装分享查看的邮件内容
+func (s *MessageShareService) sharedMessage(ctx context.Context, share *domain.MessageShare, message *domain.Message) (*SharedMessage, error) {
+	var err error
+	result := &SharedMessage{
+		From:       message.From,
+		Subject:    message.Subject,
+		Text:       message.Text,
+		ReceivedAt: message.ReceivedAt,
+		Redacted:   share.Redacted,
+		ExpiresAt:  share.ExpiresAt,
+	}
+	if share.Redacted {
+		if s.redactions == nil {
+			return nil, ErrShareLinkInvalid
+		}
+		redaction, err := s.redactions.Get(ctx, share.MailboxID, message.ID)
+		if err != nil {
+			return nil, ErrShareLinkInvalid
+		}
+		// 脱敏副本的 HTML 已在生成时清理
+		result.From, result.Subject, result.Text, result.HTML = redaction.From, redaction.Subject, redaction.Text, redaction.HTML
+	} else if message.HTML != "" {
+		if result.HTML, err = redact.SanitizeHTML(message.HTML); err != nil {
+			return nil, err
+		}
+	}
+	if share.AllowAttachments {
+		for _, att := range message.Attachments {
+			result.Attachments = append(result.Attachments, PublicAttachment{
+				ID:           att.ID,
+				Filename:     att.Filename,
+				ContentType:  att.ContentType,
+				Size:         att.Size,
+				Downloadable: true,
+
+				DetectedContentType: att.DetectedContentType,
+				ContentTypeMismatch: att.ContentTypeMismatch,
+			})
+		}
+	}
+	return result, nil
+}
+
+// OpenAttachment 按链接令牌获取附件（链接不允许下载附件时返回 ErrShareAttachmentsNotAllowed）
+func (s *MessageShareService) OpenAttachment(ctx context.Context, token, attachmentID string) (*domain.Attachment, error) {
+	share, err := s.verify(ctx, token, false)
+	if err != nil {
+		return nil, err
+	}
+	if !share.AllowAttachments {
+		return nil, ErrShareAttachmentsNotAllowed
+	}
+	message, err := s.store.GetMessage(ctx, share.MailboxID, share.MessageID)
+	if err != nil || message.Quarantined {
+		return nil, ErrShareLinkInvalid
+	}
+	return s.messages.GetAttachment(ctx, share.MailboxID, share.MessageID, attachmentID)
+}
+
+// verify 校验令牌签名、过期时间和撤销状态，返回分享记录
+//
+// mailboxWide 指定令牌应属于整个邮箱的分享还是单封邮件的分享，两种链接不能互相替代。
+func (s *MessageShareService) verify(ctx context.Context, token string, mailboxWide bool) (*domain.MessageShare, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrShareLinkInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrShareLinkInvalid
+	}
+	expected, err := s.sign(payload)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(sig, expected) {
+		return nil, ErrShareLinkInvalid
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrShareLinkInvalid
+	}
+	var claims shareClaims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, ErrShareLinkInvalid
+	}
+	if !s.now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, ErrShareLinkExpired
+	}
+
+	share, err := s.store.GetMessageShare(ctx, claims.ShareID)
+	if err != nil {
+		if errors.Is(err, storage.ErrMessageShareNotFound) {
+			return nil, ErrShareLinkInvalid
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(share.Nonce), []byte(claims.Nonce)) != 1 || share.MessageID != claims.MessageID {
+		return nil, ErrShareLinkInvalid
+	}
+	if share.MailboxWide() != mailboxWide {
+		return nil, ErrShareLinkInvalid
+	}
+	if !share.Active(s.now()) {
+		return nil, ErrShareLinkExpired
+	}
+	if mailbox, err := s.store.GetMailbox(ctx, share.MailboxID); err != nil || mailbox.Suspended {
+		return nil, ErrShareLinkInvalid
+	}
+	return share, nil
+}
+
+// link 为仍有效的分享记录生成令牌（载荷字段顺序固定，同一记录的令牌不变）
+func (s *MessageShareService) link(share *domain.MessageShare) (*MessageShareLink, error) {
+	result := &MessageShareLink{MessageShare: *share}
+	if !share.Active(s.now()) {
+		return result, nil
+	}
+
+	raw, err := json.Marshal(shareClaims{
+		ShareID:          share.ID,
+		MessageID:        share.MessageID,
+		ExpiresAt:        share.ExpiresAt.Unix(),
+		AllowAttachments: share.AllowAttachments,
+		Redacted:         share.Redacted,
+		Nonce:            share.Nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	sig, err := s.sign(payload)
+	if err != nil {
+		return nil, err
+	}
+	result.Token = payload + "." + base64.RawURLEncoding.EncodeToString(sig)
+	return result, nil
+}
+
+// sign 用由当前 JWT 密钥派生的分享密钥计算签名
+func (s *MessageShareService) sign(payload string) ([]byte, error) {
+	current, _ := s.keys.Keys()
+	key, err := hkdf.Key(sha256.New, []byte(current.Secret), nil, shareKeyLabel, sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil), nil
+}
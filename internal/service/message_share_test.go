@@ -0,0 +1,208 @@
+package service
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+type shareFixture struct {
+	store   *memory.Store
+	keys    *jwtpkg.Manager
+	shares  *MessageShareService
+	mailbox *domain.Mailbox
+	message *domain.Message
+	now     time.Time
+}
+
+func newShareFixture(t *testing.T) *shareFixture {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.example"}}}
+	mailboxes := NewMailboxService(store, store, cfg)
+	messages := NewMessageService(store)
+
+	f := &shareFixture{
+		store: store,
+		keys:  jwtpkg.NewManager(strings.Repeat("s", 32), "tempmail", time.Hour, 24*time.Hour),
+		now:   time.Now(),
+	}
+	f.shares = NewMessageShareService(store, messages, f.keys)
+	f.shares.SetRedactionService(NewRedactionService(messages, store))
+	f.shares.SetClock(func() time.Time { return f.now })
+
+	var err error
+	f.mailbox, err = mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "team", Domain: "temp.example"})
+	require.NoError(t, err)
+	f.message, err = messages.Create(t.Context(), CreateMessageInput{
+		MailboxID: f.mailbox.ID, From: "billing@vendor.example", To: f.mailbox.Address, Subject: "Invoice 42",
+		Text: "call 555-0100", HTML: `<p onclick="steal()">call 555-0100</p>`,
+		Attachments: []*domain.Attachment{
+			{ID: "att-1", Filename: "invoice.pdf", ContentType: "application/pdf", Size: 3, Content: []byte("pdf")},
+		},
+	})
+	require.NoError(t, err)
+	return f
+}
+
+func (f *shareFixture) create(t *testing.T, input CreateShareInput) *MessageShareLink {
+	t.Helper()
+	link, err := f.shares.Create(t.Context(), f.mailbox.ID, f.message.ID, input)
+	require.NoError(t, err)
+	require.NotEmpty(t, link.Token)
+	return link
+}
+
+func TestMessageShareService_Create(t *testing.T) {
+	f := newShareFixture(t)
+
+	t.Run("有效期在 1 分钟到 7 天之间", func(t *testing.T) {
+		for _, ttl := range []time.Duration{time.Second, MaxShareTTL + time.Hour, -time.Hour} {
+			_, err := f.shares.Create(t.Context(), f.mailbox.ID, f.message.ID, CreateShareInput{TTL: ttl})
+			assert.ErrorIs(t, err, ErrShareExpiryInvalid)
+		}
+		link := f.create(t, CreateShareInput{})
+		assert.WithinDuration(t, f.now.Add(DefaultShareTTL), link.ExpiresAt, time.Second)
+	})
+
+	t.Run("不能分享的邮件", func(t *testing.T) {
+		_, err := f.shares.Create(t.Context(), f.mailbox.ID, "missing", CreateShareInput{})
+		assert.ErrorIs(t, err, ErrShareMessageNotFound)
+		_, err = f.shares.Create(t.Context(), "other-mailbox", f.message.ID, CreateShareInput{})
+		assert.ErrorIs(t, err, ErrShareMessageNotFound)
+		_, err = f.shares.Create(t.Context(), f.mailbox.ID, f.message.ID, CreateShareInput{Redacted: true})
+		assert.ErrorIs(t, err, ErrShareRedactionMissing, "需先生成脱敏副本")
+
+		require.NoError(t, f.store.SetMessageQuarantined(t.Context(), f.mailbox.ID, f.message.ID, true))
+		defer func() {
+			require.NoError(t, f.store.SetMessageQuarantined(t.Context(), f.mailbox.ID, f.message.ID, false))
+		}()
+		_, err = f.shares.Create(t.Context(), f.mailbox.ID, f.message.ID, CreateShareInput{})
+		assert.ErrorIs(t, err, ErrShareNotAllowed)
+	})
+}
+
+func TestMessageShareService_Open(t *testing.T) {
+	t.Run("查看清理后的邮件并计数", func(t *testing.T) {
+		f := newShareFixture(t)
+		link := f.create(t, CreateShareInput{TTL: time.Hour})
+
+		shared, err := f.shares.Open(t.Context(), link.Token)
+		require.NoError(t, err)
+		assert.Equal(t, "Invoice 42", shared.Subject)
+		assert.NotContains(t, shared.HTML, "onclick")
+		assert.Empty(t, shared.Attachments, "未允许附件时不列出")
+		_, err = f.shares.Open(t.Context(), link.Token)
+		require.NoError(t, err)
+
+		shares, err := f.shares.List(t.Context(), f.mailbox.ID)
+		require.NoError(t, err)
+		require.Len(t, shares, 1)
+		assert.Equal(t, int64(2), shares[0].Views)
+		assert.NotNil(t, shares[0].LastViewedAt)
+		assert.Equal(t, link.Token, shares[0].Token, "列表返回同一链接")
+	})
+
+	t.Run("过期", func(t *testing.T) {
+		f := newShareFixture(t)
+		link := f.create(t, CreateShareInput{TTL: time.Hour})
+
+		f.now = f.now.Add(time.Hour + time.Second)
+		_, err := f.shares.Open(t.Context(), link.Token)
+		assert.ErrorIs(t, err, ErrShareLinkExpired)
+
+		shares, err := f.shares.List(t.Context(), f.mailbox.ID)
+		require.NoError(t, err)
+		assert.Empty(t, shares[0].Token, "过期链接不再返回令牌")
+	})
+
+	t.Run("撤销", func(t *testing.T) {
+		f := newShareFixture(t)
+		revoked := f.create(t, CreateShareInput{})
+		kept := f.create(t, CreateShareInput{})
+
+		_, err := f.shares.Revoke(t.Context(), "other-mailbox", revoked.ID)
+		assert.ErrorIs(t, err, ErrShareNotFound, "只能撤销自己邮箱的链接")
+		share, err := f.shares.Revoke(t.Context(), f.mailbox.ID, revoked.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, share.RevokedAt)
+
+		_, err = f.shares.Open(t.Context(), revoked.Token)
+		assert.ErrorIs(t, err, ErrShareLinkExpired)
+		_, err = f.shares.Open(t.Context(), kept.Token)
+		assert.NoError(t, err, "同一邮件的其他链接不受影响")
+	})
+
+	t.Run("篡改签名或载荷", func(t *testing.T) {
+		f := newShareFixture(t)
+		link := f.create(t, CreateShareInput{TTL: time.Hour})
+		payload, sig, _ := strings.Cut(link.Token, ".")
+
+		raw, err := base64.RawURLEncoding.DecodeString(payload)
+		require.NoError(t, err)
+		widened := base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(raw), `"n"`, `"att":true,"n"`, 1)))
+
+		for name, token := range map[string]string{
+			"放宽选项":  widened + "." + sig,
+			"伪造签名":  payload + "." + base64.RawURLEncoding.EncodeToString(make([]byte, 32)),
+			"缺少签名":  payload,
+			"非法编码":  payload + ".***",
+			"空令牌":   "",
+			"他人的令牌": f.create(t, CreateShareInput{}).Token[:len(payload)] + "." + sig,
+		} {
+			_, err := f.shares.Open(t.Context(), token)
+			assert.ErrorIs(t, err, ErrShareLinkInvalid, name)
+		}
+	})
+
+	t.Run("轮换 JWT 密钥后链接失效", func(t *testing.T) {
+		f := newShareFixture(t)
+		link := f.create(t, CreateShareInput{})
+		require.NoError(t, f.keys.Rotate(jwtpkg.Key{ID: "k2", Secret: strings.Repeat("r", 32)}))
+
+		_, err := f.shares.Open(t.Context(), link.Token)
+		assert.ErrorIs(t, err, ErrShareLinkInvalid)
+	})
+
+	t.Run("分享脱敏副本", func(t *testing.T) {
+		f := newShareFixture(t)
+		_, err := f.shares.redactions.CreateCopy(t.Context(), f.mailbox.ID, f.message.ID, RedactInput{Rules: []string{"phones"}})
+		require.NoError(t, err)
+		link := f.create(t, CreateShareInput{Redacted: true})
+
+		shared, err := f.shares.Open(t.Context(), link.Token)
+		require.NoError(t, err)
+		assert.True(t, shared.Redacted)
+		assert.NotContains(t, shared.Text, "555-0100")
+		assert.NotContains(t, shared.HTML, "555-0100")
+	})
+}
+
+func TestMessageShareService_Attachments(t *testing.T) {
+	f := newShareFixture(t)
+	withAttachments := f.create(t, CreateShareInput{AllowAttachments: true})
+	withoutAttachments := f.create(t, CreateShareInput{})
+
+	shared, err := f.shares.Open(t.Context(), withAttachments.Token)
+	require.NoError(t, err)
+	require.Len(t, shared.Attachments, 1)
+	assert.Equal(t, "invoice.pdf", shared.Attachments[0].Filename)
+
+	attachment, err := f.shares.OpenAttachment(t.Context(), withAttachments.Token, "att-1")
+	require.NoError(t, err)
+	assert.Equal(t, "invoice.pdf", attachment.Filename)
+
+	_, err = f.shares.OpenAttachment(t.Context(), withoutAttachments.Token, "att-1")
+	assert.ErrorIs(t, err, ErrShareAttachmentsNotAllowed)
+	_, err = f.shares.OpenAttachment(t.Context(), "bogus.token", "att-1")
+	assert.ErrorIs(t, err, ErrShareLinkInvalid)
+}
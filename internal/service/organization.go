@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+)
+
+var (
+	ErrOrgNameRequired      = errors.New("organization name required")
+	ErrOrgNotFound          = errors.New("organization not found")
+	ErrNotOrgMember         = errors.New("not a member of this organization")
+	ErrOrgOwnerRequired     = errors.New("organization owner role required")
+	ErrCannotRemoveOrgOwner = errors.New("cannot remove organization owner")
+	ErrInviteInvalid        = errors.New("invite invalid or expired")
+	ErrInviteEmailMismatch  = errors.New("invite was issued to another email")
+	ErrOrgQuotaExceeded     = errors.New("organization mailbox quota exceeded")
+	ErrUserNotFound         = errors.New("user not found")
+)
+
+// OrgInviteTTL 组织邀请有效期
+const OrgInviteTTL = 7 * 24 * time.Hour
+
+// OrgService 组织/团队服务
+type OrgService struct {
+	store domain.Store
+	authz *Authorizer
+	now   func() time.Time
+}
+
+// NewOrgService 创建组织服务
+func NewOrgService(store domain.Store) *OrgService {
+	return &OrgService{
+		store: store,
+		authz: NewAuthorizer(store),
+		now:   time.Now,
+	}
+}
+
+// OrgWithRole 组织及当前用户的角色
+type OrgWithRole struct {
+	*domain.Organization
+	Role domain.OrgRole `json:"role"`
+}
+
+// Create 创建组织，创建者成为 owner；组织等级沿用创建者的等级
+func (s *OrgService) Create(ctx context.Context, userID, name string) (*domain.Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrOrgNameRequired
+	}
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	now := s.now().UTC()
+	org := &domain.Organization{
+		ID:        uuid.NewString(),
+		Name:      name,
+		OwnerID:   userID,
+		Tier:      user.Tier,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.store.CreateOrganization(ctx, org); err != nil {
+		return nil, err
+	}
+	if err := s.store.SaveOrgMember(ctx, &domain.OrgMember{
+		OrgID:    org.ID,
+		UserID:   userID,
+		Role:     domain.OrgRoleOwner,
+		JoinedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// ListForUser 列出用户所在的组织
+func (s *OrgService) ListForUser(ctx context.Context, userID string) ([]OrgWithRole, error) {
+	memberships, err := s.store.ListOrgMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]OrgWithRole, 0, len(memberships))
+	for _, m := range memberships {
+		org, err := s.store.GetOrganization(ctx, m.OrgID)
+		if err != nil {
+			continue
+		}
+		result = append(result, OrgWithRole{Organization: org, Role: m.Role})
+	}
+	return result, nil
+}
+
+// Invite 邀请用户加入组织（仅 owner），返回包含加入令牌的邀请
+//
+// 受邀者一律以 member 角色加入，邀请不能授予 owner 权限。
+func (s *OrgService) Invite(ctx context.Context, userID, orgID, email string) (*domain.OrgInvite, error) {
+	if _, err := s.authz.RequireOrgRole(ctx, userID, orgID, domain.OrgRoleOwner); err != nil {
+		return nil, err
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, domain.ErrInvalidEmail
+	}
+
+	now := s.now().UTC()
+	invite := &domain.OrgInvite{
+		Token:     generateToken(24),
+		OrgID:     orgID,
+		Email:     email,
+		InvitedBy: userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(OrgInviteTTL),
+	}
+	if err := s.store.SaveOrgInvite(ctx, invite); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// Accept 凭加入令牌加入组织
+//
+// 令牌只能使用一次：先删除邀请再登记成员，并发接受时只有一个请求成功。
+func (s *OrgService) Accept(ctx context.Context, userID, email, token string) (*domain.OrgMember, error) {
+	invite, err := s.store.GetOrgInvite(ctx, token)
+	if err != nil {
+		return nil, ErrInviteInvalid
+	}
+	if !strings.EqualFold(invite.Email, strings.TrimSpace(email)) {
+		return nil, ErrInviteEmailMismatch
+	}
+	if err := s.store.DeleteOrgInvite(ctx, token); err != nil {
+		return nil, ErrInviteInvalid
+	}
+	if s.now().After(invite.ExpiresAt) {
+		return nil, ErrInviteInvalid
+	}
+
+	// 已是成员时保持原角色，避免降级 owner
+	if existing, err := s.store.GetOrgMember(ctx, invite.OrgID, userID); err == nil {
+		return existing, nil
+	}
+
+	member := &domain.OrgMember{
+		OrgID:    invite.OrgID,
+		UserID:   userID,
+		Role:     domain.OrgRoleMember,
+		JoinedAt: s.now().UTC(),
+	}
+	if err := s.store.SaveOrgMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// ListMembers 列出组织成员（仅成员可见）
+func (s *OrgService) ListMembers(ctx context.Context, userID, orgID string) ([]*domain.OrgMember, error) {
+	if _, err := s.authz.RequireOrgRole(ctx, userID, orgID, domain.OrgRoleMember); err != nil {
+		return nil, err
+	}
+	return s.store.ListOrgMembers(ctx, orgID)
+}
+
+// RemoveMember 移除组织成员
+//
+// owner 可以移除其他成员，成员可以移除自己（退出组织）；组织创建者不能被移除。
+func (s *OrgService) RemoveMember(ctx context.Context, userID, orgID, targetUserID string) error {
+	org, err := s.store.GetOrganization(ctx, orgID)
+	if err != nil {
+		return ErrOrgNotFound
+	}
+	if targetUserID != userID {
+		if _, err := s.authz.RequireOrgRole(ctx, userID, orgID, domain.OrgRoleOwner); err != nil {
+			return err
+		}
+	} else if _, err := s.authz.RequireOrgRole(ctx, userID, orgID, domain.OrgRoleMember); err != nil {
+		return err
+	}
+	if targetUserID == org.OwnerID {
+		return ErrCannotRemoveOrgOwner
+	}
+	if err := s.store.DeleteOrgMember(ctx, orgID, targetUserID); err != nil {
+		return ErrNotOrgMember
+	}
+	return nil
+}
+
+// Memberships 获取用户的组织成员关系（按组织ID排序）
+func (s *OrgService) Memberships(ctx context.Context, userID string) ([]domain.OrgMembership, error) {
+	members, err := s.store.ListOrgMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.OrgMembership, 0, len(members))
+	for _, m := range members {
+		result = append(result, domain.OrgMembership{OrgID: m.OrgID, Role: m.Role})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].OrgID < result[j].OrgID })
+	return result, nil
+}
+
+// TokenOrgs 返回写入 JWT 的组织声明及版本戳（用作 jwt.OrgResolver）
+func (s *OrgService) TokenOrgs(ctx context.Context, userID string) ([]jwt.OrgClaim, string) {
+	memberships, err := s.Memberships(ctx, userID)
+	if err != nil || len(memberships) == 0 {
+		return nil, ""
+	}
+	claims := make([]jwt.OrgClaim, 0, len(memberships))
+	for _, m := range memberships {
+		claims = append(claims, jwt.OrgClaim{OrgID: m.OrgID, Role: string(m.Role)})
+	}
+	return claims, MembershipStamp(memberships)
+}
+
+// MembershipStamp 计算成员关系指纹，用于判断 JWT 中的组织声明是否过期
+func MembershipStamp(memberships []domain.OrgMembership) string {
+	if len(memberships) == 0 {
+		return ""
+	}
+	sorted := append([]domain.OrgMembership(nil), memberships...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].OrgID < sorted[j].OrgID })
+	h := sha256.New()
+	for _, m := range sorted {
+		h.Write([]byte(m.OrgID + ":" + string(m.Role) + ";"))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// OrgIDsForUser 获取用户所在组织的ID列表
+func OrgIDsForUser(ctx context.Context, store domain.Store, userID string) []string {
+	members, err := store.ListOrgMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.OrgID)
+	}
+	return ids
+}
+
+// checkOrgMailboxQuota 检查组织邮箱配额（-1 表示不限）
+func checkOrgMailboxQuota(ctx context.Context, store domain.Store, orgID string) error {
+	org, err := store.GetOrganization(ctx, orgID)
+	if err != nil {
+		return ErrOrgNotFound
+	}
+	limit := domain.DefaultQuotas(org.Tier).MaxMailboxes
+	if limit < 0 {
+		return nil
+	}
+	if len(store.ListMailboxesByOrgID(ctx, orgID)) >= limit {
+		return ErrOrgQuotaExceeded
+	}
+	return nil
+}
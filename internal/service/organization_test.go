@@ -0,0 +1,231 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+type orgFixture struct {
+	store     *memory.Store
+	orgs      *OrgService
+	mailboxes *MailboxService
+	domains   *UserDomainService
+	tags      *TagService
+	webhooks  *WebhookService
+	authz     *Authorizer
+}
+
+func newOrgFixture(t *testing.T, users ...string) *orgFixture {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	for _, id := range users {
+		require.NoError(t, store.CreateUser(t.Context(), &domain.User{
+			ID: id, Email: id + "@corp.example", Username: id, Tier: domain.TierFree, IsActive: true, CreatedAt: time.Now(),
+		}))
+	}
+
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"corp.example"}}}
+	domains := NewUserDomainService(store, cfg)
+	mailboxes := NewMailboxService(store, store, cfg)
+	mailboxes.SetUserDomainService(domains)
+	return &orgFixture{
+		store:     store,
+		orgs:      NewOrgService(store),
+		mailboxes: mailboxes,
+		domains:   domains,
+		tags:      NewTagService(store),
+		webhooks:  NewWebhookService(store),
+		authz:     NewAuthorizer(store),
+	}
+}
+
+// join 邀请并接受，返回成员关系
+func (f *orgFixture) join(t *testing.T, ownerID, orgID, userID string) {
+	t.Helper()
+	invite, err := f.orgs.Invite(t.Context(), ownerID, orgID, userID+"@corp.example")
+	require.NoError(t, err)
+	_, err = f.orgs.Accept(t.Context(), userID, userID+"@corp.example", invite.Token)
+	require.NoError(t, err)
+}
+
+func TestOrgService_MemberAccess(t *testing.T) {
+	f := newOrgFixture(t, "alice", "bob", "carol")
+	org, err := f.orgs.Create(t.Context(), "alice", "QA Team")
+	require.NoError(t, err)
+	f.join(t, "alice", org.ID, "bob")
+
+	alice := "alice"
+	shared, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "shared", UserID: &alice, OrgID: &org.ID})
+	require.NoError(t, err)
+	private, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "private", UserID: &alice})
+	require.NoError(t, err)
+
+	t.Run("成员可以读写组织邮箱", func(t *testing.T) {
+		assert.True(t, f.authz.CanAccessMailbox(t.Context(), "bob", shared, ActionRead))
+		assert.True(t, f.authz.CanAccessMailbox(t.Context(), "bob", shared, ActionWrite))
+
+		ids := map[string]bool{}
+		for _, mb := range f.mailboxes.ListByUserID(t.Context(), "bob") {
+			ids[mb.ID] = true
+		}
+		assert.True(t, ids[shared.ID])
+		assert.False(t, ids[private.ID])
+	})
+
+	t.Run("个人资源对其他成员保持私有", func(t *testing.T) {
+		assert.False(t, f.authz.CanAccessMailbox(t.Context(), "bob", private, ActionRead))
+		assert.True(t, f.authz.CanAccessMailbox(t.Context(), "alice", private, ActionWrite))
+
+		tag, err := f.tags.CreateTag(t.Context(), CreateTagInput{UserID: "alice", Name: "mine", Color: "#fff"})
+		require.NoError(t, err)
+		assert.False(t, f.authz.Can(t.Context(), "bob", tag.UserID, tag.OrgID, ActionRead))
+		tags, err := f.tags.ListTags(t.Context(), "bob")
+		require.NoError(t, err)
+		assert.Empty(t, tags)
+	})
+
+	t.Run("非成员无法访问组织资源", func(t *testing.T) {
+		assert.False(t, f.authz.CanAccessMailbox(t.Context(), "carol", shared, ActionRead))
+		_, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "sneaky", UserID: strPtr("carol"), OrgID: &org.ID})
+		assert.ErrorIs(t, err, ErrNotOrgMember)
+		_, err = f.orgs.ListMembers(t.Context(), "carol", org.ID)
+		assert.ErrorIs(t, err, ErrNotOrgMember)
+	})
+
+	t.Run("组织 Webhook 和标签对成员可见", func(t *testing.T) {
+		webhook, err := f.webhooks.CreateWebhook(t.Context(), CreateWebhookInput{
+			UserID: "alice", OrgID: &org.ID, URL: "https://hooks.example.com", Events: []string{"mail.received"},
+		})
+		require.NoError(t, err)
+		webhooks, err := f.webhooks.ListWebhooks(t.Context(), "bob")
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, webhook.ID, webhooks[0].ID)
+
+		_, err = f.tags.CreateTag(t.Context(), CreateTagInput{UserID: "bob", OrgID: &org.ID, Name: "team", Color: "#000"})
+		require.NoError(t, err)
+		tags, err := f.tags.ListTags(t.Context(), "alice")
+		require.NoError(t, err)
+		names := []string{}
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		assert.Contains(t, names, "team")
+	})
+}
+
+func TestOrgService_RoleEscalation(t *testing.T) {
+	f := newOrgFixture(t, "alice", "bob", "mallory")
+	org, err := f.orgs.Create(t.Context(), "alice", "QA Team")
+	require.NoError(t, err)
+	f.join(t, "alice", org.ID, "bob")
+
+	t.Run("成员不能邀请他人", func(t *testing.T) {
+		_, err := f.orgs.Invite(t.Context(), "bob", org.ID, "mallory@corp.example")
+		assert.ErrorIs(t, err, ErrOrgOwnerRequired)
+	})
+
+	t.Run("成员不能移除他人或所有者", func(t *testing.T) {
+		assert.ErrorIs(t, f.orgs.RemoveMember(t.Context(), "bob", org.ID, "alice"), ErrOrgOwnerRequired)
+		assert.ErrorIs(t, f.orgs.RemoveMember(t.Context(), "alice", org.ID, "alice"), ErrCannotRemoveOrgOwner)
+	})
+
+	t.Run("成员不能管理组织域名", func(t *testing.T) {
+		_, err := f.domains.AddDomain(t.Context(), AddDomainInput{UserID: "bob", OrgID: &org.ID, Domain: "team.example", Mode: domain.DomainModeShared})
+		assert.ErrorIs(t, err, ErrOrgOwnerRequired)
+
+		owned, err := f.domains.AddDomain(t.Context(), AddDomainInput{UserID: "alice", OrgID: &org.ID, Domain: "team.example", Mode: domain.DomainModeShared})
+		require.NoError(t, err)
+		_, err = f.domains.GetUserDomain(t.Context(), owned.ID, "bob")
+		assert.NoError(t, err, "成员可以查看组织域名")
+		_, err = f.domains.UpdateDomainMode(t.Context(), owned.ID, "bob", domain.DomainModeExclusive)
+		assert.ErrorIs(t, err, ErrNotDomainOwner)
+		assert.ErrorIs(t, f.domains.DeleteUserDomain(t.Context(), owned.ID, "bob"), ErrNotDomainOwner)
+	})
+
+	t.Run("邀请令牌一次性且绑定邮箱", func(t *testing.T) {
+		invite, err := f.orgs.Invite(t.Context(), "alice", org.ID, "mallory@corp.example")
+		require.NoError(t, err)
+
+		_, err = f.orgs.Accept(t.Context(), "bob", "bob@corp.example", invite.Token)
+		assert.ErrorIs(t, err, ErrInviteEmailMismatch)
+
+		member, err := f.orgs.Accept(t.Context(), "mallory", "mallory@corp.example", invite.Token)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrgRoleMember, member.Role)
+
+		_, err = f.orgs.Accept(t.Context(), "mallory", "mallory@corp.example", invite.Token)
+		assert.ErrorIs(t, err, ErrInviteInvalid)
+	})
+
+	t.Run("过期邀请无法使用", func(t *testing.T) {
+		invite, err := f.orgs.Invite(t.Context(), "alice", org.ID, "late@corp.example")
+		require.NoError(t, err)
+		f.orgs.now = func() time.Time { return time.Now().Add(OrgInviteTTL + time.Hour) }
+		defer func() { f.orgs.now = time.Now }()
+
+		_, err = f.orgs.Accept(t.Context(), "late", "late@corp.example", invite.Token)
+		assert.ErrorIs(t, err, ErrInviteInvalid)
+	})
+}
+
+func TestOrgService_RemovalRevokesAccess(t *testing.T) {
+	f := newOrgFixture(t, "alice", "bob")
+	org, err := f.orgs.Create(t.Context(), "alice", "QA Team")
+	require.NoError(t, err)
+	f.join(t, "alice", org.ID, "bob")
+
+	// bob 创建的组织邮箱，被移除后也不能再访问
+	bob := "bob"
+	mailbox, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "bobs", UserID: &bob, OrgID: &org.ID})
+	require.NoError(t, err)
+	require.True(t, f.authz.CanAccessMailbox(t.Context(), "bob", mailbox, ActionWrite))
+
+	claims, stampBefore := f.orgs.TokenOrgs(t.Context(), "bob")
+	require.Len(t, claims, 1)
+
+	require.NoError(t, f.orgs.RemoveMember(t.Context(), "alice", org.ID, "bob"))
+
+	t.Run("移除后立即失去访问权限", func(t *testing.T) {
+		assert.False(t, f.authz.CanAccessMailbox(t.Context(), "bob", mailbox, ActionRead))
+		assert.Empty(t, f.mailboxes.ListByUserID(t.Context(), "bob"))
+		assert.True(t, f.authz.CanAccessMailbox(t.Context(), "alice", mailbox, ActionWrite))
+	})
+
+	t.Run("成员关系版本戳变化", func(t *testing.T) {
+		claims, stampAfter := f.orgs.TokenOrgs(t.Context(), "bob")
+		assert.Empty(t, claims)
+		assert.NotEqual(t, stampBefore, stampAfter)
+	})
+}
+
+func TestOrgService_Quota(t *testing.T) {
+	f := newOrgFixture(t, "alice")
+	org, err := f.orgs.Create(t.Context(), "alice", "QA Team")
+	require.NoError(t, err)
+
+	alice := "alice"
+	limit := domain.DefaultQuotas(domain.TierFree).MaxMailboxes
+	for i := 0; i < limit; i++ {
+		_, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{UserID: &alice, OrgID: &org.ID})
+		require.NoError(t, err)
+	}
+
+	_, err = f.mailboxes.Create(t.Context(), CreateMailboxInput{UserID: &alice, OrgID: &org.ID})
+	assert.ErrorIs(t, err, ErrOrgQuotaExceeded)
+
+	// 个人邮箱不占用组织配额
+	_, err = f.mailboxes.Create(t.Context(), CreateMailboxInput{UserID: &alice})
+	assert.NoError(t, err)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
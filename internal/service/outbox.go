@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// 发件箱投递目标名（记录在事件的 Delivered 中，改名会导致已部分投递的事件重复投递）
+const (
+	OutboxTargetWebSocket = "websocket"
+	OutboxTargetWebhook   = "webhook"
+	OutboxTargetPubSub    = "pubsub"
+)
+
+const (
+	// outboxLease 认领事件的时长：写入方在此期间投递自己写入的事件，超时（如进程崩溃）后由重试任务接手
+	outboxLease = time.Minute
+	// outboxBatchSize 每次重试认领的事件数
+	outboxBatchSize = 100
+)
+
+// outboxRetryIntervals 投递失败后的重试间隔（新邮件通知时效短，比 Webhook 的间隔短），用尽后放弃
+var outboxRetryIntervals = []time.Duration{
+	5 * time.Second,
+	30 * time.Second,
+	2 * time.Minute,
+	10 * time.Minute,
+	1 * time.Hour,
+}
+
+// OutboxHandler 发件箱事件的投递目标，返回错误时事件稍后重试（已成功的目标不再投递）
+type OutboxHandler func(ctx context.Context, message *domain.Message) error
+
+// outboxTarget 已注册的投递目标
+type outboxTarget struct {
+	name   string
+	handle OutboxHandler
+}
+
+// OutboxDispatcher 事务性发件箱分发器
+//
+// 新邮件事件与邮件在同一事务写入并由写入方认领，入库流程结束后写入方立即异步投递；
+// 写入方崩溃或投递失败的事件由 RetryPending 在认领过期后重新认领。
+// 每个目标投递成功后立即记录，重试时跳过已成功的目标，全部成功后事件标记为已处理。
+type OutboxDispatcher struct {
+	repo     storage.OutboxRepository
+	messages *MessageService
+	targets  []outboxTarget
+	inflight sync.WaitGroup // 写入方发起的异步投递
+	now      func() time.Time
+}
+
+// NewOutboxDispatcher 创建分发器，messages 用于在重试时重新读取邮件（含正文和附件）
+func NewOutboxDispatcher(repo storage.OutboxRepository, messages *MessageService) *OutboxDispatcher {
+	return &OutboxDispatcher{repo: repo, messages: messages, now: time.Now}
+}
+
+// Register 注册投递目标（启动时调用，按注册顺序投递）
+func (d *OutboxDispatcher) Register(name string, handler OutboxHandler) {
+	d.targets = append(d.targets, outboxTarget{name: name, handle: handler})
+}
+
+// newEvents 为新邮件创建事件，以写入方认领的状态写入，认领期内重试任务不会取到
+func (d *OutboxDispatcher) newEvents(messages []*domain.Message) []*domain.OutboxEvent {
+	now := d.now().UTC()
+	claimedUntil := now.Add(outboxLease)
+	events := make([]*domain.OutboxEvent, len(messages))
+	for i, message := range messages {
+		events[i] = &domain.OutboxEvent{
+			ID:            uuid.NewString(),
+			Type:          domain.OutboxEventMailReceived,
+			MailboxID:     message.MailboxID,
+			MessageID:     message.ID,
+			ClaimedUntil:  &claimedUntil,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+	}
+	return events
+}
+
+// dispatch 写入方在入库流程结束后投递自己写入的事件（异步，按顺序；邮件已经入库，投递不随请求取消，
+// 但保留请求上下文中的链路追踪信息）
+func (d *OutboxDispatcher) dispatch(ctx context.Context, events []*domain.OutboxEvent, messages []*domain.Message) {
+	ctx = context.WithoutCancel(ctx)
+	d.inflight.Add(1)
+	go func() {
+		defer d.inflight.Done()
+		for i, event := range events {
+			d.deliver(ctx, event, messages[i])
+		}
+	}()
+}
+
+// Wait 等待写入方发起的投递结束（关闭时调用；之后写入的事件由重试任务处理）
+func (d *OutboxDispatcher) Wait() {
+	d.inflight.Wait()
+}
+
+// RetryPending 认领到期未处理的事件并投递，返回认领的事件数
+func (d *OutboxDispatcher) RetryPending(ctx context.Context) (int, error) {
+	now := d.now().UTC()
+	events, err := d.repo.ClaimOutboxEvents(ctx, now, now.Add(outboxLease), outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		message, err := d.messages.Get(ctx, event.MailboxID, event.MessageID)
+		if err != nil {
+			// 各存储的“邮件不存在”错误不统一，与投递失败一样重试，次数用尽后放弃
+			d.fail(ctx, event, err)
+			continue
+		}
+		d.deliver(ctx, event, message)
+	}
+	return len(events), nil
+}
+
+// Cleanup 删除处理完成超过 retention 的事件，返回删除数
+func (d *OutboxDispatcher) Cleanup(ctx context.Context, retention time.Duration) (int64, error) {
+	return d.repo.DeleteProcessedOutboxEvents(ctx, d.now().UTC().Add(-retention))
+}
+
+// deliver 依次投递到尚未成功的目标，每个目标成功后保存进度；任一目标失败时安排重试
+func (d *OutboxDispatcher) deliver(ctx context.Context, event *domain.OutboxEvent, message *domain.Message) {
+	for i, target := range d.targets {
+		if event.IsDelivered(target.name) {
+			continue
+		}
+		if err := target.handle(ctx, message); err != nil {
+			d.fail(ctx, event, err)
+			return
+		}
+		event.Delivered = append(event.Delivered, target.name)
+		if i < len(d.targets)-1 {
+			_ = d.repo.UpdateOutboxEvent(ctx, event)
+		}
+	}
+	d.finish(ctx, event, nil)
+}
+
+// finish 标记事件已处理（cause 非空表示放弃投递的原因）
+func (d *OutboxDispatcher) finish(ctx context.Context, event *domain.OutboxEvent, cause error) {
+	now := d.now().UTC()
+	event.ProcessedAt = &now
+	event.ClaimedUntil = nil
+	if cause != nil {
+		event.LastError = cause.Error()
+	}
+	_ = d.repo.UpdateOutboxEvent(ctx, event)
+}
+
+// fail 记录失败并按退避安排下次投递，重试次数用尽后放弃
+func (d *OutboxDispatcher) fail(ctx context.Context, event *domain.OutboxEvent, err error) {
+	event.Attempts++
+	index := event.Attempts - 1
+	if index >= len(outboxRetryIntervals) {
+		d.finish(ctx, event, err)
+		return
+	}
+	event.LastError = err.Error()
+	event.NextAttemptAt = d.now().UTC().Add(outboxRetryIntervals[index])
+	event.ClaimedUntil = nil
+	_ = d.repo.UpdateOutboxEvent(ctx, event)
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// outboxRecorder 记录各目标收到的邮件，可让目标失败
+type outboxRecorder struct {
+	mu       sync.Mutex
+	received map[string][]string
+	failing  map[string]error
+}
+
+func newOutboxRecorder() *outboxRecorder {
+	return &outboxRecorder{received: make(map[string][]string), failing: make(map[string]error)}
+}
+
+func (r *outboxRecorder) handler(target string) OutboxHandler {
+	return func(ctx context.Context, message *domain.Message) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if err := r.failing[target]; err != nil {
+			return err
+		}
+		r.received[target] = append(r.received[target], message.ID)
+		return nil
+	}
+}
+
+func (r *outboxRecorder) fail(target string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failing[target] = err
+}
+
+func (r *outboxRecorder) ids(target string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.received[target]...)
+}
+
+func TestOutboxDispatcher(t *testing.T) {
+	setup := func(t *testing.T) (*memory.Store, *MessageService, *OutboxDispatcher, *outboxRecorder, *domain.Mailbox) {
+		store := memory.NewStore(24 * time.Hour)
+		mailbox := &domain.Mailbox{ID: "mb-1", Address: "box@temp.example", LocalPart: "box", Domain: "temp.example", CreatedAt: time.Now()}
+		require.NoError(t, store.SaveMailbox(t.Context(), mailbox))
+
+		messages := NewMessageService(store)
+		dispatcher := NewOutboxDispatcher(store, messages)
+		recorder := newOutboxRecorder()
+		dispatcher.Register(OutboxTargetWebSocket, recorder.handler(OutboxTargetWebSocket))
+		dispatcher.Register(OutboxTargetWebhook, recorder.handler(OutboxTargetWebhook))
+		messages.SetOutbox(dispatcher)
+		return store, messages, dispatcher, recorder, mailbox
+	}
+
+	t.Run("入库后投递到全部目标并标记已处理", func(t *testing.T) {
+		_, messages, dispatcher, recorder, mailbox := setup(t)
+		message, err := messages.Create(t.Context(), CreateMessageInput{MailboxID: mailbox.ID, From: "a@example.com", Subject: "hi"})
+		require.NoError(t, err)
+		dispatcher.Wait()
+
+		assert.Equal(t, []string{message.ID}, recorder.ids(OutboxTargetWebSocket))
+		assert.Equal(t, []string{message.ID}, recorder.ids(OutboxTargetWebhook))
+
+		// 已处理的事件不会被再次认领，超过保留期后被清理
+		dispatcher.now = func() time.Time { return time.Now().Add(2 * outboxLease) }
+		claimed, err := dispatcher.RetryPending(t.Context())
+		require.NoError(t, err)
+		assert.Zero(t, claimed)
+		deleted, err := dispatcher.Cleanup(t.Context(), time.Minute)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, deleted)
+	})
+
+	t.Run("失败的目标重试时不重复投递已成功的目标", func(t *testing.T) {
+		_, messages, dispatcher, recorder, mailbox := setup(t)
+		recorder.fail(OutboxTargetWebhook, errors.New("webhook down"))
+		message, err := messages.Create(t.Context(), CreateMessageInput{MailboxID: mailbox.ID, Subject: "retry"})
+		require.NoError(t, err)
+		dispatcher.Wait()
+		require.Equal(t, []string{message.ID}, recorder.ids(OutboxTargetWebSocket))
+		require.Empty(t, recorder.ids(OutboxTargetWebhook))
+
+		// 未到重试时间时不认领
+		claimed, err := dispatcher.RetryPending(t.Context())
+		require.NoError(t, err)
+		assert.Zero(t, claimed)
+
+		recorder.fail(OutboxTargetWebhook, nil)
+		dispatcher.now = func() time.Time { return time.Now().Add(outboxRetryIntervals[0] + time.Second) }
+		claimed, err = dispatcher.RetryPending(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 1, claimed)
+		assert.Equal(t, []string{message.ID}, recorder.ids(OutboxTargetWebSocket), "WebSocket 已成功，不再推送")
+		assert.Equal(t, []string{message.ID}, recorder.ids(OutboxTargetWebhook))
+	})
+
+	t.Run("写入方未投递的事件在认领过期后补发", func(t *testing.T) {
+		store, messages, dispatcher, recorder, mailbox := setup(t)
+		// 模拟提交后崩溃：邮件和事件已写入，写入方没来得及投递
+		message, err := messages.newMessage(CreateMessageInput{MailboxID: mailbox.ID, Subject: "crash"}, nil)
+		require.NoError(t, err)
+		events := dispatcher.newEvents([]*domain.Message{message})
+		require.NoError(t, store.SaveMessagesWithOutbox(t.Context(), []*domain.Message{message}, events))
+
+		claimed, err := dispatcher.RetryPending(t.Context())
+		require.NoError(t, err)
+		assert.Zero(t, claimed, "认领期内属于写入方")
+
+		dispatcher.now = func() time.Time { return time.Now().Add(outboxLease + time.Second) }
+		claimed, err = dispatcher.RetryPending(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 1, claimed)
+		assert.Equal(t, []string{message.ID}, recorder.ids(OutboxTargetWebSocket))
+		assert.Equal(t, []string{message.ID}, recorder.ids(OutboxTargetWebhook))
+
+		// 同一事件只投递一次
+		claimed, err = dispatcher.RetryPending(t.Context())
+		require.NoError(t, err)
+		assert.Zero(t, claimed)
+	})
+
+	t.Run("邮箱不存在时邮件和事件都不写入", func(t *testing.T) {
+		store, messages, dispatcher, _, _ := setup(t)
+		_, err := messages.Create(t.Context(), CreateMessageInput{MailboxID: "missing", Subject: "x"})
+		require.Error(t, err)
+
+		dispatcher.now = func() time.Time { return time.Now().Add(outboxLease + time.Second) }
+		events, err := store.ClaimOutboxEvents(t.Context(), dispatcher.now(), dispatcher.now().Add(outboxLease), 10)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/redact"
+	"tempmail/backend/internal/security"
+	"tempmail/backend/internal/storage"
+)
+
+// PublicInboxRetention 公开收件箱中邮件的保留时长（固定值，不受其他设置影响）
+const PublicInboxRetention = time.Hour
+
+// PublicAttachmentMaxBytes 公开收件箱可下载附件的大小上限
+const PublicAttachmentMaxBytes = 5 << 20
+
+// 公开收件箱分页
+const (
+	DefaultPublicPageSize = 20
+	MaxPublicPageSize     = 50
+)
+
+// publicPreviewLength 邮件预览的最大字符数
+const publicPreviewLength = 120
+
+var (
+	// ErrPublicInboxNotFound 邮箱不存在或未公开（两种情况不作区分，避免探测私有地址）
+	ErrPublicInboxNotFound      = errors.New("public inbox not found")
+	ErrPublicMessageNotFound    = errors.New("public message not found")
+	ErrPublicAttachmentTooLarge = errors.New("attachment exceeds public download limit")
+)
+
+// PublicAccessRevoker 取消公开时撤销匿名订阅（由 WebSocket Hub 实现）
+type PublicAccessRevoker interface {
+	RevokePublicAccess(mailboxID string)
+}
+
+// SetPublicAccessRevoker 设置公开访问撤销通知（避免依赖 websocket 包）
+func (s *MailboxService) SetPublicAccessRevoker(revoker PublicAccessRevoker) {
+	s.publicRevoker = revoker
+}
+
+// SetPublic 设置邮箱是否为公开收件箱（仅管理员调用）
+//
+// 取消公开立即生效：通过公开权限建立的 WebSocket 订阅会被撤销。
+func (s *MailboxService) SetPublic(ctx context.Context, id string, public bool) (*domain.Mailbox, error) {
+	mailbox, err := s.repo.GetMailbox(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if mailbox.IsPublic == public {
+		return mailbox, nil
+	}
+
+	mailbox.IsPublic = public
+	if err := s.repo.SaveMailbox(ctx, mailbox); err != nil {
+		return nil, err
+	}
+	if !public && s.publicRevoker != nil {
+		s.publicRevoker.RevokePublicAccess(id)
+	}
+	return mailbox, nil
+}
+
+// allowsPublicInboxes 检查系统域名是否允许创建时直接设为公开收件箱
+func (s *MailboxService) allowsPublicInboxes(ctx context.Context, domainName string) bool {
+	if s.store == nil {
+		return false
+	}
+	sysDomain, err := s.store.GetSystemDomainByDomain(ctx, domainName)
+	return err == nil && sysDomain.AllowPublicInboxes
+}
+
+// PublicInbox 公开收件箱列表项
+type PublicInbox struct {
+	Address   string     `json:"address"`
+	Unread    int        `json:"unread"`
+	Total     int        `json:"total"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// PublicMessagePreview 公开收件箱中的邮件预览（不含正文）
+type PublicMessagePreview struct {
+	ID             string    `json:"id"`
+	Seq            int64     `json:"seq"` // 邮箱内入库序号
+	From           string    `json:"from"`
+	Subject        string    `json:"subject"`
+	Preview        string    `json:"preview"`
+	HasAttachments bool      `json:"hasAttachments"`
+	ReceivedAt     time.Time `json:"receivedAt"`
+	ExpiresAt      time.Time `json:"expiresAt"` // 保留期结束、自动删除的时间
+}
+
+// PublicMessagePage 公开收件箱邮件分页
+type PublicMessagePage struct {
+	Items    []PublicMessagePreview `json:"items"`
+	Total    int                    `json:"total"`
+	Page     int                    `json:"page"`
+	PageSize int                    `json:"pageSize"`
+}
+
+// PublicAttachment 公开邮件的附件信息
+type PublicAttachment struct {
+	ID           string `json:"id"`
+	Filename     string `json:"filename"`
+	ContentType  string `json:"contentType"`
+	Size         int64  `json:"size"`
+	Downloadable bool   `json:"downloadable"` // 超过公开下载上限时为 false
+
+	DetectedContentType string `json:"detectedContentType,omitempty"` // 按内容判断的实际类型
+	ContentTypeMismatch bool   `json:"contentTypeMismatch"`           // 声明类型与实际类型不一致（可能是伪装附件）
+}
+
+// PublicMessage 公开邮件详情（HTML 已清理）
+type PublicMessage struct {
+	ID          string             `json:"id"`
+	From        string             `json:"from"`
+	To          string             `json:"to"`
+	Subject     string             `json:"subject"`
+	Text        string             `json:"text"`
+	HTML        string             `json:"html"`
+	ReceivedAt  time.Time          `json:"receivedAt"`
+	ExpiresAt   time.Time          `json:"expiresAt"`
+	Attachments []PublicAttachment `json:"attachments"`
+}
+
+// PublicInboxService 公开收件箱（只读）
+//
+// 公开收件箱无需令牌即可查看，只提供读取：不标记已读，HTML 清理后返回，附件限制大小；
+// 邮件在 PublicInboxRetention 后自动删除（清理前也不再展示），隔离区邮件不公开。
+type PublicInboxService struct {
+	store    storage.Store
+	messages *MessageService
+	now      func() time.Time
+}
+
+// NewPublicInboxService 创建公开收件箱服务
+func NewPublicInboxService(store storage.Store, messages *MessageService) *PublicInboxService {
+	return &PublicInboxService{store: store, messages: messages, now: time.Now}
+}
+
+// List 列出所有公开收件箱
+func (s *PublicInboxService) List(ctx context.Context) ([]PublicInbox, error) {
+	mailboxes, err := s.store.ListPublicMailboxes(ctx, s.now())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]PublicInbox, 0, len(mailboxes))
+	for _, mb := range mailboxes {
+		if mb.Suspended {
+			continue
+		}
+		result = append(result, PublicInbox{
+			Address:   mb.Address,
+			Unread:    mb.Unread,
+			Total:     mb.TotalCount,
+			ExpiresAt: mb.ExpiresAt,
+		})
+	}
+	return result, nil
+}
+
+// Resolve 按地址获取公开收件箱
+func (s *PublicInboxService) Resolve(ctx context.Context, address string) (*domain.Mailbox, error) {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if address == "" {
+		return nil, ErrPublicInboxNotFound
+	}
+	mailbox, err := s.store.GetMailboxByAddress(ctx, address)
+	if err != nil || mailbox == nil || !mailbox.IsPublic || mailbox.Suspended {
+		return nil, ErrPublicInboxNotFound
+	}
+	return mailbox, nil
+}
+
+// ListMessages 分页列出公开收件箱中的邮件预览（新邮件在前）
+func (s *PublicInboxService) ListMessages(ctx context.Context, address string, page, pageSize int) (*PublicMessagePage, error) {
+	mailbox, err := s.Resolve(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.visibleMessages(ctx, mailbox.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPublicPageSize
+	}
+	if pageSize > MaxPublicPageSize {
+		pageSize = MaxPublicPageSize
+	}
+
+	result := &PublicMessagePage{Items: []PublicMessagePreview{}, Total: len(messages), Page: page, PageSize: pageSize}
+	start := (page - 1) * pageSize
+	if start >= len(messages) {
+		return result, nil
+	}
+	end := min(start+pageSize, len(messages))
+	for _, msg := range messages[start:end] {
+		result.Items = append(result.Items, PublicMessagePreview{
+			ID:             msg.ID,
+			Seq:            msg.Seq,
+			From:           msg.From,
+			Subject:        msg.Subject,
+			Preview:        Preview(&msg),
+			HasAttachments: len(msg.Attachments) > 0,
+			ReceivedAt:     msg.ReceivedAt,
+			ExpiresAt:      msg.ReceivedAt.Add(PublicInboxRetention),
+		})
+	}
+	return result, nil
+}
+
+// GetMessage 获取公开邮件详情（HTML 已清理，不标记已读）
+func (s *PublicInboxService) GetMessage(ctx context.Context, address, messageID string) (*PublicMessage, error) {
+	mailbox, message, err := s.visibleMessage(ctx, address, messageID)
+	if err != nil {
+		return nil, err
+	}
+	full, err := s.messages.Get(ctx, mailbox.ID, message.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sanitized := ""
+	if full.HTML != "" {
+		if sanitized, err = redact.SanitizeHTML(full.HTML); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &PublicMessage{
+		ID:          full.ID,
+		From:        full.From,
+		To:          full.To,
+		Subject:     full.Subject,
+		Text:        full.Text,
+		HTML:        sanitized,
+		ReceivedAt:  full.ReceivedAt,
+		ExpiresAt:   full.ReceivedAt.Add(PublicInboxRetention),
+		Attachments: make([]PublicAttachment, 0, len(full.Attachments)),
+	}
+	for _, att := range full.Attachments {
+		result.Attachments = append(result.Attachments, PublicAttachment{
+			ID:           att.ID,
+			Filename:     att.Filename,
+			ContentType:  att.ContentType,
+			Size:         att.Size,
+			Downloadable: att.Size <= PublicAttachmentMaxBytes,
+
+			DetectedContentType: att.DetectedContentType,
+			ContentTypeMismatch: att.ContentTypeMismatch,
+		})
+	}
+	return result, nil
+}
+
+// GetAttachment 获取公开邮件的附件（超过公开下载上限时返回 ErrPublicAttachmentTooLarge）
+func (s *PublicInboxService) GetAttachment(ctx context.Context, address, messageID, attachmentID string) (*domain.Attachment, error) {
+	mailbox, message, err := s.visibleMessage(ctx, address, messageID)
+	if err != nil {
+		return nil, err
+	}
+	attachment, err := s.messages.GetAttachment(ctx, mailbox.ID, message.ID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.Size > PublicAttachmentMaxBytes {
+		return nil, ErrPublicAttachmentTooLarge
+	}
+	return attachment, nil
+}
+
+// SweepExpired 删除公开收件箱中超过保留时长的邮件，返回删除数量
+func (s *PublicInboxService) SweepExpired() (int, error) {
+	mailboxes, err := s.store.ListPublicMailboxes(context.Background(), s.now())
+	if err != nil {
+		return 0, err
+	}
+	cutoff := s.now().Add(-PublicInboxRetention)
+
+	deleted := 0
+	var errs []error
+	for _, mb := range mailboxes {
+		messages, err := s.store.ListMessages(context.Background(), mb.ID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, msg := range messages {
+			if !msg.ReceivedAt.Before(cutoff) {
+				continue
+			}
+			if err := s.messages.Delete(context.Background(), mb.ID, msg.ID); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			deleted++
+		}
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// visibleMessages 列出保留期内、不在隔离区的邮件（新邮件在前）
+func (s *PublicInboxService) visibleMessages(ctx context.Context, mailboxID string) ([]domain.Message, error) {
+	messages, err := s.messages.List(ctx, mailboxID)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := s.now().Add(-PublicInboxRetention)
+	visible := messages[:0]
+	for _, msg := range messages {
+		if !msg.ReceivedAt.Before(cutoff) {
+			visible = append(visible, msg)
+		}
+	}
+	domain.SortMessagesBySeq(visible)
+	return visible, nil
+}
+
+// visibleMessage 获取公开收件箱中可见的单封邮件元数据
+func (s *PublicInboxService) visibleMessage(ctx context.Context, address, messageID string) (*domain.Mailbox, *domain.Message, error) {
+	mailbox, err := s.Resolve(ctx, address)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 各存储实现的“邮件不存在”错误不同，公开接口统一视为不存在
+	message, err := s.store.GetMessage(ctx, mailbox.ID, messageID)
+	if err != nil || message.Quarantined || message.ReceivedAt.Before(s.now().Add(-PublicInboxRetention)) {
+		return nil, nil, ErrPublicMessageNotFound
+	}
+	return mailbox, message, nil
+}
+
+// Preview 生成邮件预览（纯文本优先，按字符截断；公开收件箱和回放工具共用）
+func Preview(message *domain.Message) string {
+	text := message.Text
+	if text == "" && message.HTML != "" {
+		text = security.ExtractText(message.HTML)
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= publicPreviewLength {
+		return text
+	}
+	return string([]rune(text)[:publicPreviewLength]) + "…"
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// recordingRevoker 记录取消公开的邮箱
+type recordingRevoker struct {
+	revoked []string
+}
+
+func (r *recordingRevoker) RevokePublicAccess(mailboxID string) {
+	r.revoked = append(r.revoked, mailboxID)
+}
+
+type publicInboxFixture struct {
+	store     *memory.Store
+	mailboxes *MailboxService
+	messages  *MessageService
+	inboxes   *PublicInboxService
+	revoker   *recordingRevoker
+	now       time.Time
+}
+
+func newPublicInboxFixture(t *testing.T) *publicInboxFixture {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"corp.example", "open.example"}}}
+	f := &publicInboxFixture{
+		store:     store,
+		mailboxes: NewMailboxService(store, store, cfg),
+		messages:  NewMessageService(store),
+		revoker:   &recordingRevoker{},
+		now:       time.Now().UTC(), // 内存存储按真实时间判断过期
+	}
+	f.mailboxes.SetPublicAccessRevoker(f.revoker)
+	f.inboxes = NewPublicInboxService(store, f.messages)
+	f.inboxes.now = func() time.Time { return f.now }
+
+	require.NoError(t, store.SaveSystemDomain(t.Context(), &domain.SystemDomain{
+		ID: "sd-open", Domain: "open.example", Status: domain.SystemDomainStatusVerified, IsActive: true, AllowPublicInboxes: true,
+	}))
+	return f
+}
+
+func (f *publicInboxFixture) addMessage(t *testing.T, mailboxID, subject string, received time.Time, attachments ...*domain.Attachment) *domain.Message {
+	t.Helper()
+	msg, err := f.messages.Create(t.Context(), CreateMessageInput{
+		MailboxID: mailboxID, From: "a@example.com", Subject: subject,
+		Text: "hello " + subject, HTML: `<p onclick="x()">hello</p><script>alert(1)</script>`,
+		Received: received, Attachments: attachments,
+	})
+	require.NoError(t, err)
+	return msg
+}
+
+func TestPublicInboxService(t *testing.T) {
+	t.Run("只有公开收件箱可匿名查看", func(t *testing.T) {
+		f := newPublicInboxFixture(t)
+		private, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "secret", Domain: "corp.example"})
+		require.NoError(t, err)
+		public, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "lobby", Domain: "open.example", Public: true})
+		require.NoError(t, err)
+		assert.True(t, public.IsPublic)
+
+		_, err = f.inboxes.ListMessages(t.Context(), private.Address, 1, 20)
+		assert.ErrorIs(t, err, ErrPublicInboxNotFound)
+		_, err = f.inboxes.ListMessages(t.Context(), "missing@open.example", 1, 20)
+		assert.ErrorIs(t, err, ErrPublicInboxNotFound)
+
+		inboxes, err := f.inboxes.List(t.Context())
+		require.NoError(t, err)
+		require.Len(t, inboxes, 1)
+		assert.Equal(t, public.Address, inboxes[0].Address)
+	})
+
+	t.Run("域名未开启时不能创建公开收件箱", func(t *testing.T) {
+		f := newPublicInboxFixture(t)
+		_, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "lobby", Domain: "corp.example", Public: true})
+		assert.ErrorIs(t, err, ErrPublicInboxNotAllowed)
+	})
+
+	t.Run("预览新邮件在前且 HTML 已清理", func(t *testing.T) {
+		f := newPublicInboxFixture(t)
+		mailbox, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "lobby", Domain: "open.example", Public: true})
+		require.NoError(t, err)
+		f.addMessage(t, mailbox.ID, "older", f.now.Add(-10*time.Minute))
+		newer := f.addMessage(t, mailbox.ID, "newer", f.now.Add(-time.Minute))
+
+		page, err := f.inboxes.ListMessages(t.Context(), mailbox.Address, 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, page.Total)
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, "newer", page.Items[0].Subject)
+		assert.Equal(t, "hello newer", page.Items[0].Preview)
+
+		message, err := f.inboxes.GetMessage(t.Context(), mailbox.Address, newer.ID)
+		require.NoError(t, err)
+		assert.NotContains(t, message.HTML, "<script")
+		assert.NotContains(t, message.HTML, "onclick")
+
+		stored, err := f.store.GetMessage(t.Context(), mailbox.ID, newer.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.IsRead, "公开查看不标记已读")
+	})
+
+	t.Run("超过上限的附件不可下载", func(t *testing.T) {
+		f := newPublicInboxFixture(t)
+		mailbox, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "lobby", Domain: "open.example", Public: true})
+		require.NoError(t, err)
+		msg := f.addMessage(t, mailbox.ID, "files", f.now,
+			&domain.Attachment{ID: "small", Filename: "a.txt", ContentType: "text/plain", Size: 3, Content: []byte("abc")},
+			&domain.Attachment{ID: "big", Filename: "b.bin", ContentType: "application/octet-stream", Size: PublicAttachmentMaxBytes + 1},
+		)
+
+		message, err := f.inboxes.GetMessage(t.Context(), mailbox.Address, msg.ID)
+		require.NoError(t, err)
+		require.Len(t, message.Attachments, 2)
+		assert.True(t, message.Attachments[0].Downloadable)
+		assert.False(t, message.Attachments[1].Downloadable)
+
+		_, err = f.inboxes.GetAttachment(t.Context(), mailbox.Address, msg.ID, "small")
+		assert.NoError(t, err)
+		_, err = f.inboxes.GetAttachment(t.Context(), mailbox.Address, msg.ID, "big")
+		assert.ErrorIs(t, err, ErrPublicAttachmentTooLarge)
+	})
+
+	t.Run("超过保留时长的邮件被隐藏并清理", func(t *testing.T) {
+		f := newPublicInboxFixture(t)
+		mailbox, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "lobby", Domain: "open.example", Public: true})
+		require.NoError(t, err)
+		private, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "secret", Domain: "corp.example"})
+		require.NoError(t, err)
+		stale := f.addMessage(t, mailbox.ID, "stale", f.now.Add(-PublicInboxRetention-time.Minute))
+		f.addMessage(t, mailbox.ID, "fresh", f.now.Add(-time.Minute))
+		f.addMessage(t, private.ID, "private", f.now.Add(-2*PublicInboxRetention))
+
+		page, err := f.inboxes.ListMessages(t.Context(), mailbox.Address, 1, 20)
+		require.NoError(t, err)
+		assert.Equal(t, 1, page.Total, "清理前也不展示过期邮件")
+		_, err = f.inboxes.GetMessage(t.Context(), mailbox.Address, stale.ID)
+		assert.ErrorIs(t, err, ErrPublicMessageNotFound)
+
+		deleted, err := f.inboxes.SweepExpired()
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		remaining, err := f.store.ListMessages(t.Context(), mailbox.ID)
+		require.NoError(t, err)
+		require.Len(t, remaining, 1)
+		assert.Equal(t, "fresh", remaining[0].Subject)
+
+		untouched, err := f.store.ListMessages(t.Context(), private.ID)
+		require.NoError(t, err)
+		assert.Len(t, untouched, 1, "私有邮箱不受公开保留时长影响")
+	})
+
+	t.Run("取消公开后立即不可见并撤销订阅", func(t *testing.T) {
+		f := newPublicInboxFixture(t)
+		mailbox, err := f.mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "lobby", Domain: "open.example", Public: true})
+		require.NoError(t, err)
+
+		_, err = f.mailboxes.SetPublic(t.Context(), mailbox.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{mailbox.ID}, f.revoker.revoked)
+
+		_, err = f.inboxes.ListMessages(t.Context(), mailbox.Address, 1, 20)
+		assert.ErrorIs(t, err, ErrPublicInboxNotFound)
+
+		_, err = f.mailboxes.SetPublic(t.Context(), mailbox.ID, true)
+		require.NoError(t, err)
+		assert.Len(t, f.revoker.revoked, 1, "设为公开不触发撤销")
+	})
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ErrMailboxQuotaExceeded 用户个人邮箱数量已达等级上限
+var ErrMailboxQuotaExceeded = errors.New("mailbox quota exceeded")
+
+// QuotaError 配额用尽错误，携带配额范围、上限和当前用量
+// （errors.Is 匹配 ErrMailboxQuotaExceeded 或 ErrMailboxFull）
+type QuotaError struct {
+	Scope string // QuotaScope* 之一
+	Limit int
+	Used  int
+	Err   error
+}
+
+func (e *QuotaError) Error() string { return e.Err.Error() }
+
+func (e *QuotaError) Unwrap() error { return e.Err }
+
+// mailboxTier 邮箱适用的等级：组织邮箱按组织等级，用户邮箱按所有者等级，游客邮箱按免费等级
+func mailboxTier(ctx context.Context, store domain.Store, mailbox *domain.Mailbox) domain.UserTier {
+	if domain.InOrg(mailbox.OrgID) {
+		if org, err := store.GetOrganization(ctx, *mailbox.OrgID); err == nil {
+			return org.Tier
+		}
+	} else if mailbox.UserID != nil {
+		if user, err := store.GetUserByID(ctx, *mailbox.UserID); err == nil {
+			return user.Tier
+		}
+	}
+	return domain.TierFree
+}
+
+// checkMessageQuota 检查邮箱的邮件数量配额（按 mailboxTier，-1 表示不限），用尽时触发 quota.exceeded
+func checkMessageQuota(ctx context.Context, store domain.Store, notifier QuotaExceededNotifier, mailbox *domain.Mailbox) error {
+	limit := domain.DefaultQuotas(mailboxTier(ctx, store, mailbox)).MaxMessagesPerMailbox
+	if limit < 0 || mailbox.TotalCount < limit {
+		return nil
+	}
+	if notifier != nil {
+		notifier.NotifyQuotaExceeded(QuotaExceededData{
+			Scope: QuotaScopeMailboxMessages, Limit: limit, MailboxID: mailbox.ID,
+		})
+	}
+	return &QuotaError{Scope: QuotaScopeMailboxMessages, Limit: limit, Used: mailbox.TotalCount, Err: ErrMailboxFull}
+}
+
+// checkUserMailboxQuota 检查用户还能否再创建 n 个个人邮箱（组织邮箱计入组织配额，不在此统计）
+func checkUserMailboxQuota(ctx context.Context, store domain.Store, userID string, n int) error {
+	user, err := store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil // 用户不存在时由认证和授权处理
+	}
+	limit := domain.DefaultQuotas(user.Tier).MaxMailboxes
+	if limit < 0 {
+		return nil
+	}
+	used := 0
+	for _, mailbox := range store.ListMailboxesByUserID(ctx, userID) {
+		if !domain.InOrg(mailbox.OrgID) {
+			used++
+		}
+	}
+	if used+n <= limit {
+		return nil
+	}
+	return &QuotaError{Scope: QuotaScopeUserMailboxes, Limit: limit, Used: used, Err: ErrMailboxQuotaExceeded}
+}
+
+// CheckMessageQuota 检查邮箱是否还能接收新邮件（SMTP 在 RCPT 阶段、HTTP 在创建邮件前调用）
+//
+// 用尽时返回 *QuotaError（errors.Is 匹配 ErrMailboxFull）并触发 quota.exceeded。
+func (s *MailboxService) CheckMessageQuota(ctx context.Context, mailbox *domain.Mailbox) error {
+	if s.store == nil || mailbox == nil {
+		return nil
+	}
+	return checkMessageQuota(ctx, s.store, s.quotaNotifier, mailbox)
+}
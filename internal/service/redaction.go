@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/redact"
+	"tempmail/backend/internal/security"
+	"tempmail/backend/internal/storage"
+)
+
+var ErrNothingToRedact = errors.New("message has no content to redact")
+
+// RedactInput 脱敏规则：内置规则名和/或自定义正则
+type RedactInput struct {
+	Rules    []string
+	Patterns []string
+}
+
+// RedactionService 邮件脱敏副本服务
+type RedactionService struct {
+	messages *MessageService
+	repo     storage.RedactionRepository
+	now      func() time.Time
+}
+
+// NewRedactionService 创建脱敏副本服务
+func NewRedactionService(messages *MessageService, repo storage.RedactionRepository) *RedactionService {
+	return &RedactionService{
+		messages: messages,
+		repo:     repo,
+		now:      time.Now,
+	}
+}
+
+// CreateCopy 生成邮件的脱敏副本并保存（覆盖已有副本）
+//
+// 主题、发件人和纯文本正文逐一遮盖；HTML 正文先清理脚本等危险内容再遮盖，保留原有结构。
+// 没有纯文本正文时从 HTML 提取。原始邮件不受影响。
+func (s *RedactionService) CreateCopy(ctx context.Context, mailboxID, messageID string, input RedactInput) (*domain.MessageRedaction, error) {
+	redactor, err := redact.New(input.Rules, input.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := s.messages.Get(ctx, mailboxID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Text == "" && message.HTML == "" && message.Subject == "" {
+		return nil, ErrNothingToRedact
+	}
+
+	text := message.Text
+	if text == "" && message.HTML != "" {
+		text = security.ExtractText(message.HTML)
+	}
+
+	redaction := &domain.MessageRedaction{
+		MessageID:    message.ID,
+		MailboxID:    message.MailboxID,
+		RulesVersion: redact.Version,
+		Rules:        redactor.Rules(),
+		Patterns:     redactor.Patterns(),
+		Subject:      redactor.Text(message.Subject),
+		From:         redactor.Text(message.From),
+		Text:         redactor.Text(text),
+		CreatedAt:    s.now(),
+	}
+	if message.HTML != "" {
+		if redaction.HTML, err = redactor.HTML(message.HTML); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.SaveMessageRedaction(ctx, redaction); err != nil {
+		return nil, err
+	}
+	return redaction, nil
+}
+
+// Get 获取邮件的脱敏副本
+func (s *RedactionService) Get(ctx context.Context, mailboxID, messageID string) (*domain.MessageRedaction, error) {
+	return s.repo.GetMessageRedaction(ctx, mailboxID, messageID)
+}
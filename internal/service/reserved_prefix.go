@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// MaxVanityPrefixesPerUser 每个用户同时有效的靓号前缀上限
+const MaxVanityPrefixesPerUser = 5
+
+// 靓号前缀的预留时长
+const (
+	DefaultVanityReservation = 30 * 24 * time.Hour
+	MaxVanityReservation     = 365 * 24 * time.Hour
+)
+
+var (
+	ErrPrefixReserved           = errors.New("prefix is reserved")
+	ErrReservedPrefixInvalid    = errors.New("reserved prefix is invalid")
+	ErrReservedPrefixExists     = errors.New("prefix is already reserved")
+	ErrReservedPrefixNotFound   = errors.New("reserved prefix not found")
+	ErrReservedDomainInvalid    = errors.New("prefixes can only be reserved on shared system domains")
+	ErrVanityRequiresPaidTier   = errors.New("vanity prefixes require a paid plan")
+	ErrVanityLimitReached       = errors.New("too many vanity prefixes")
+	ErrReservationPeriodInvalid = errors.New("invalid reservation period")
+)
+
+// ReservedPrefixService 系统域名保留前缀
+//
+// 配置中的前缀（mailbox.reserved_prefixes）和管理员添加的前缀在系统域名上禁止任何人创建邮箱；
+// 付费用户可以在共享域名上预留靓号前缀，有效期内只有本人可以创建。用户域名由所有者自行管理，不受限制。
+// 已过期的条目不再生效，再次预留同一前缀时覆盖。
+type ReservedPrefixService struct {
+	store         domain.Store
+	prefixes      storage.ReservedPrefixRepository
+	builtin       map[string]struct{}
+	shared        map[string]struct{} // 可以预留靓号的共享域名（mailbox.allowed_domains）
+	defaultDomain string
+	validator     *domain.EmailValidator
+	now           func() time.Time
+}
+
+// NewReservedPrefixService 创建保留前缀服务
+func NewReservedPrefixService(store domain.Store, prefixes storage.ReservedPrefixRepository, cfg *config.Config) *ReservedPrefixService {
+	s := &ReservedPrefixService{
+		store:     store,
+		prefixes:  prefixes,
+		builtin:   make(map[string]struct{}, len(cfg.Mailbox.ReservedPrefixes)),
+		shared:    make(map[string]struct{}, len(cfg.Mailbox.AllowedDomains)),
+		validator: domain.NewEmailValidator(),
+		now:       time.Now,
+	}
+	for _, prefix := range cfg.Mailbox.ReservedPrefixes {
+		s.builtin[strings.ToLower(prefix)] = struct{}{}
+	}
+	for _, d := range cfg.Mailbox.AllowedDomains {
+		s.shared[strings.ToLower(d)] = struct{}{}
+	}
+	if len(cfg.Mailbox.AllowedDomains) > 0 {
+		s.defaultDomain = strings.ToLower(cfg.Mailbox.AllowedDomains[0])
+	}
+	return s
+}
+
+// SetClock 替换时间来源（测试用）
+func (s *ReservedPrefixService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// BuiltinPrefixes 配置中禁止使用的前缀
+func (s *ReservedPrefixService) BuiltinPrefixes() []string {
+	prefixes := make([]string, 0, len(s.builtin))
+	for prefix := range s.builtin {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// BlockPrefixInput 管理员屏蔽前缀的参数
+type BlockPrefixInput struct {
+	Domain    string // 为空时对所有系统域名生效
+	LocalPart string
+	Reason    string
+	ExpiresAt *time.Time // 为空表示永久
+}
+
+// List 列出全部保留前缀（管理员）
+func (s *ReservedPrefixService) List(ctx context.Context) ([]*domain.ReservedPrefix, error) {
+	return s.prefixes.ListReservedPrefixes(ctx, nil)
+}
+
+// Block 屏蔽前缀（管理员），已有靓号预留的同一域名前缀需先删除
+func (s *ReservedPrefixService) Block(ctx context.Context, input BlockPrefixInput) (*domain.ReservedPrefix, error) {
+	domainName := strings.ToLower(strings.TrimSpace(input.Domain))
+	if domainName != "" && ResolveDomain(ctx, s.store, domainName).Kind == DomainKindUser {
+		return nil, ErrReservedDomainInvalid
+	}
+	localPart, err := s.normalize(input.LocalPart)
+	if err != nil {
+		return nil, err
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(s.now()) {
+		return nil, ErrReservationPeriodInvalid
+	}
+
+	prefix := &domain.ReservedPrefix{
+		ID:        uuid.NewString(),
+		Domain:    domainName,
+		LocalPart: localPart,
+		Reason:    strings.TrimSpace(input.Reason),
+		ExpiresAt: input.ExpiresAt,
+		CreatedAt: s.now().UTC(),
+	}
+	if err := s.create(ctx, prefix, nil); err != nil {
+		return nil, err
+	}
+	return prefix, nil
+}
+
+// Remove 删除保留前缀（管理员，也可用于收回用户的靓号）
+func (s *ReservedPrefixService) Remove(ctx context.Context, id string) error {
+	if err := s.prefixes.DeleteReservedPrefix(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrReservedPrefixNotFound) {
+			return ErrReservedPrefixNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ReservePrefixInput 用户预留靓号前缀的参数
+type ReservePrefixInput struct {
+	Domain    string // 为空时使用默认域名
+	LocalPart string
+	Duration  time.Duration // 为 0 时使用 DefaultVanityReservation
+}
+
+// ListForUser 列出用户预留的靓号前缀（含已过期但尚未被覆盖的条目）
+func (s *ReservedPrefixService) ListForUser(ctx context.Context, userID string) ([]*domain.ReservedPrefix, error) {
+	return s.prefixes.ListReservedPrefixes(ctx, &userID)
+}
+
+// Reserve 为付费用户预留共享域名上的靓号前缀，本人已预留的同一前缀按新的时长续期
+func (s *ReservedPrefixService) Reserve(ctx context.Context, userID string, input ReservePrefixInput) (*domain.ReservedPrefix, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Tier == "" || user.Tier == domain.TierFree {
+		return nil, ErrVanityRequiresPaidTier
+	}
+	duration := input.Duration
+	if duration == 0 {
+		duration = DefaultVanityReservation
+	}
+	if duration < 0 || duration > MaxVanityReservation {
+		return nil, ErrReservationPeriodInvalid
+	}
+	domainName := strings.ToLower(strings.TrimSpace(input.Domain))
+	if domainName == "" {
+		domainName = s.defaultDomain
+	}
+	if _, ok := s.shared[domainName]; !ok || ResolveDomain(ctx, s.store, domainName).Kind == DomainKindUser {
+		return nil, ErrReservedDomainInvalid
+	}
+	localPart, err := s.normalize(input.LocalPart)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.builtin[localPart]; ok {
+		return nil, ErrPrefixReserved
+	}
+
+	now := s.now()
+	owned, err := s.prefixes.ListReservedPrefixes(ctx, &userID)
+	if err != nil {
+		return nil, err
+	}
+	active := 0
+	for _, prefix := range owned {
+		if prefix.Active(now) && !(prefix.Domain == domainName && prefix.LocalPart == localPart) {
+			active++
+		}
+	}
+	if active >= MaxVanityPrefixesPerUser {
+		return nil, ErrVanityLimitReached
+	}
+
+	expiresAt := now.Add(duration).UTC()
+	prefix := &domain.ReservedPrefix{
+		ID:        uuid.NewString(),
+		Domain:    domainName,
+		LocalPart: localPart,
+		UserID:    &userID,
+		ExpiresAt: &expiresAt,
+		CreatedAt: now.UTC(),
+	}
+	if err := s.create(ctx, prefix, &userID); err != nil {
+		return nil, err
+	}
+	return prefix, nil
+}
+
+// Release 释放用户预留的靓号前缀
+func (s *ReservedPrefixService) Release(ctx context.Context, userID, id string) error {
+	owned, err := s.prefixes.ListReservedPrefixes(ctx, &userID)
+	if err != nil {
+		return err
+	}
+	for _, prefix := range owned {
+		if prefix.ID == id {
+			return s.Remove(ctx, id)
+		}
+	}
+	return ErrReservedPrefixNotFound
+}
+
+// CheckCreate 检查在域名下以指定前缀创建邮箱是否被保留（随机前缀和用户域名不检查）
+func (s *ReservedPrefixService) CheckCreate(ctx context.Context, domainName, prefix string, userID *string) error {
+	if prefix == "" {
+		return nil
+	}
+	res := ResolveDomain(ctx, s.store, domainName)
+	if res.Kind == DomainKindUser {
+		return nil
+	}
+	prefix = strings.ToLower(prefix)
+	if _, ok := s.builtin[prefix]; ok {
+		return ErrPrefixReserved
+	}
+	entries, err := s.prefixes.FindReservedPrefixes(ctx, res.Domain, prefix)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	for _, entry := range entries {
+		if !entry.Active(now) {
+			continue
+		}
+		if entry.UserID == nil || userID == nil || *entry.UserID != *userID {
+			return ErrPrefixReserved
+		}
+	}
+	return nil
+}
+
+// create 保存条目：与已有条目冲突时，已过期的或属于 owner 的靓号被覆盖，
+// 否则返回 ErrReservedPrefixExists；所有系统域名上屏蔽的前缀不能再预留为靓号
+func (s *ReservedPrefixService) create(ctx context.Context, prefix *domain.ReservedPrefix, owner *string) error {
+	existing, err := s.prefixes.FindReservedPrefixes(ctx, prefix.Domain, prefix.LocalPart)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	for _, entry := range existing {
+		if entry.Domain != prefix.Domain {
+			if owner != nil && entry.UserID == nil && entry.Active(now) {
+				return ErrPrefixReserved
+			}
+			continue
+		}
+		replaceable := !entry.Active(now) ||
+			(owner != nil && entry.UserID != nil && *entry.UserID == *owner)
+		if !replaceable {
+			return ErrReservedPrefixExists
+		}
+		if err := s.prefixes.DeleteReservedPrefix(ctx, entry.ID); err != nil && !errors.Is(err, storage.ErrReservedPrefixNotFound) {
+			return err
+		}
+	}
+	if err := s.prefixes.CreateReservedPrefix(ctx, prefix); err != nil {
+		if errors.Is(err, storage.ErrReservedPrefixExists) {
+			return ErrReservedPrefixExists
+		}
+		return err
+	}
+	return nil
+}
+
+// normalize 校验并转为小写前缀
+func (s *ReservedPrefixService) normalize(localPart string) (string, error) {
+	localPart = strings.ToLower(strings.TrimSpace(localPart))
+	if localPart == "" || s.validator.ValidateLocalPart(localPart) != nil {
+		return "", ErrReservedPrefixInvalid
+	}
+	return localPart, nil
+}
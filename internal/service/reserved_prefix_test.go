@@ -0,0 +1,116 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestReservedPrefixService(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: "paid", Email: "paid@example.com", Username: "paid", Tier: domain.TierPro}))
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: "free", Email: "free@example.com", Username: "free", Tier: domain.TierFree}))
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{
+		ID: "ud-1", UserID: "free", Domain: "own.example", Mode: domain.DomainModeShared,
+		Status: domain.DomainStatusVerified, IsActive: true,
+	}))
+	paid, free := "paid", "free"
+
+	cfg := &config.Config{}
+	cfg.Mailbox.AllowedDomains = []string{"temp.mail", "own.example"}
+	cfg.Mailbox.ReservedPrefixes = []string{"postmaster"}
+	now := time.Now()
+	reserved := NewReservedPrefixService(store, store, cfg)
+	reserved.SetClock(func() time.Time { return now })
+	mailboxes := NewMailboxService(store, store, cfg)
+	mailboxes.SetReservedPrefixes(reserved)
+
+	t.Run("配置和管理员屏蔽的前缀", func(t *testing.T) {
+		_, err := mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "PostMaster", Domain: "temp.mail", UserID: &paid})
+		assert.ErrorIs(t, err, ErrPrefixReserved)
+
+		blocked, err := reserved.Block(t.Context(), BlockPrefixInput{LocalPart: " Billing ", Reason: "财务"})
+		require.NoError(t, err)
+		assert.Equal(t, "billing", blocked.LocalPart)
+		_, err = reserved.Block(t.Context(), BlockPrefixInput{LocalPart: "billing"})
+		assert.ErrorIs(t, err, ErrReservedPrefixExists)
+		_, err = reserved.Block(t.Context(), BlockPrefixInput{LocalPart: "bad name"})
+		assert.ErrorIs(t, err, ErrReservedPrefixInvalid)
+
+		_, err = mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "billing", Domain: "temp.mail"})
+		assert.ErrorIs(t, err, ErrPrefixReserved)
+		_, err = reserved.Reserve(t.Context(), paid, ReservePrefixInput{Domain: "temp.mail", LocalPart: "billing"})
+		assert.ErrorIs(t, err, ErrPrefixReserved, "屏蔽的前缀不能预留为靓号")
+	})
+
+	t.Run("用户域名不受限制", func(t *testing.T) {
+		mailbox, err := mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "postmaster", Domain: "own.example", UserID: &free})
+		require.NoError(t, err)
+		assert.Equal(t, "postmaster@own.example", mailbox.Address)
+	})
+
+	t.Run("靓号前缀只有本人可以创建", func(t *testing.T) {
+		_, err := reserved.Reserve(t.Context(), free, ReservePrefixInput{LocalPart: "vip"})
+		assert.ErrorIs(t, err, ErrVanityRequiresPaidTier)
+		_, err = reserved.Reserve(t.Context(), paid, ReservePrefixInput{Domain: "own.example", LocalPart: "vip"})
+		assert.ErrorIs(t, err, ErrReservedDomainInvalid)
+		_, err = reserved.Reserve(t.Context(), paid, ReservePrefixInput{LocalPart: "vip", Duration: 2 * MaxVanityReservation})
+		assert.ErrorIs(t, err, ErrReservationPeriodInvalid)
+
+		vanity, err := reserved.Reserve(t.Context(), paid, ReservePrefixInput{LocalPart: "VIP"})
+		require.NoError(t, err)
+		assert.Equal(t, "temp.mail", vanity.Domain)
+		assert.Equal(t, now.Add(DefaultVanityReservation).UTC(), *vanity.ExpiresAt)
+
+		_, err = mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "vip", Domain: "temp.mail", UserID: &free})
+		assert.ErrorIs(t, err, ErrPrefixReserved)
+		_, err = mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "vip", Domain: "temp.mail"})
+		assert.ErrorIs(t, err, ErrPrefixReserved)
+		mailbox, err := mailboxes.Create(t.Context(), CreateMailboxInput{Prefix: "vip", Domain: "temp.mail", UserID: &paid})
+		require.NoError(t, err)
+		assert.Equal(t, "vip@temp.mail", mailbox.Address)
+	})
+
+	t.Run("续期和过期后他人可以预留", func(t *testing.T) {
+		renewed, err := reserved.Reserve(t.Context(), paid, ReservePrefixInput{LocalPart: "vip", Duration: 90 * 24 * time.Hour})
+		require.NoError(t, err)
+		owned, err := reserved.ListForUser(t.Context(), paid)
+		require.NoError(t, err)
+		require.Len(t, owned, 1)
+		assert.Equal(t, renewed.ID, owned[0].ID)
+
+		require.NoError(t, store.UpdateUser(t.Context(), &domain.User{ID: "free", Email: "free@example.com", Username: "free", Tier: domain.TierBasic}))
+		_, err = reserved.Reserve(t.Context(), free, ReservePrefixInput{LocalPart: "vip"})
+		assert.ErrorIs(t, err, ErrReservedPrefixExists)
+
+		now = now.Add(91 * 24 * time.Hour)
+		taken, err := reserved.Reserve(t.Context(), free, ReservePrefixInput{LocalPart: "vip"})
+		require.NoError(t, err)
+		assert.Equal(t, free, *taken.UserID)
+		owned, err = reserved.ListForUser(t.Context(), paid)
+		require.NoError(t, err)
+		assert.Empty(t, owned)
+	})
+
+	t.Run("数量上限和释放", func(t *testing.T) {
+		var last *domain.ReservedPrefix
+		for i := range MaxVanityPrefixesPerUser {
+			var err error
+			last, err = reserved.Reserve(t.Context(), paid, ReservePrefixInput{LocalPart: "mine" + string(rune('a'+i))})
+			require.NoError(t, err)
+		}
+		_, err := reserved.Reserve(t.Context(), paid, ReservePrefixInput{LocalPart: "onemore"})
+		assert.ErrorIs(t, err, ErrVanityLimitReached)
+
+		assert.ErrorIs(t, reserved.Release(t.Context(), free, last.ID), ErrReservedPrefixNotFound, "不能释放他人的前缀")
+		require.NoError(t, reserved.Release(t.Context(), paid, last.ID))
+		_, err = reserved.Reserve(t.Context(), paid, ReservePrefixInput{LocalPart: "onemore"})
+		assert.NoError(t, err)
+	})
+}
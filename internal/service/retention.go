@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"tempmail/backend/internal/domain"
+)
+
+const (
+	// RetentionSweepGrace 清理任务的执行间隔，超过保留期限再过该时长仍存在的记录视为逾期
+	RetentionSweepGrace = time.Hour
+	// UnverifiedDomainRetention 未验证系统域名的保留时长（与 CleanupUnverifiedDomains 一致）
+	UnverifiedDomainRetention = 24 * time.Hour
+
+	// retentionUserPageSize 报告遍历用户时的分页大小
+	retentionUserPageSize = 500
+)
+
+// 数据类别
+const (
+	RetentionCategoryMailboxes     = "mailboxes"
+	RetentionCategoryMessages      = "messages"
+	RetentionCategorySystemDomains = "unverified_system_domains"
+	RetentionCategoryUserDomains   = "user_domains"
+	RetentionCategoryUsers         = "users"
+)
+
+// retentionBuckets 按记录年龄分桶（上限不含，最后一桶无上限）
+var retentionBuckets = []struct {
+	label string
+	upper time.Duration
+}{
+	{"<1h", time.Hour},
+	{"1h-24h", 24 * time.Hour},
+	{"1d-7d", 7 * 24 * time.Hour},
+	{"7d-30d", 30 * 24 * time.Hour},
+	{">30d", 0},
+}
+
+// RetentionReport 数据保留报告
+type RetentionReport struct {
+	GeneratedAt time.Time           `json:"generatedAt"`
+	Categories  []RetentionCategory `json:"categories"`
+}
+
+// RetentionCategory 单个数据类别的保留情况
+//
+// Satisfied 为空表示该类别没有自动删除策略（保留到用户或管理员删除）。
+type RetentionCategory struct {
+	Category  string            `json:"category"`
+	Policy    string            `json:"policy"`
+	Total     int               `json:"total"`
+	Oldest    *time.Time        `json:"oldest,omitempty"`
+	Buckets   []RetentionBucket `json:"buckets"`
+	Overdue   int               `json:"overdue"`
+	Satisfied *bool             `json:"satisfied,omitempty"`
+}
+
+// RetentionBucket 年龄分桶计数
+type RetentionBucket struct {
+	Age   string `json:"age"`
+	Count int    `json:"count"`
+}
+
+// RetentionService 数据保留报告服务（供运营方证明删除时限）
+type RetentionService struct {
+	store domain.Store
+	audit DataAuditFunc
+	now   func() time.Time
+}
+
+// NewRetentionService 创建数据保留报告服务
+func NewRetentionService(store domain.Store) *RetentionService {
+	return &RetentionService{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// SetAuditFunc 设置审计回调
+func (s *RetentionService) SetAuditFunc(fn DataAuditFunc) {
+	s.audit = fn
+}
+
+// Report 统计各数据类别的最早记录、年龄分布以及保留期限是否满足
+//
+// 报告只包含计数和时间，不含任何用户的具体数据。
+func (s *RetentionService) Report(ctx context.Context, actorID string) (*RetentionReport, error) {
+	now := s.now()
+
+	mailboxes, err := s.retainedMailboxes(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		byMailbox     map[string][]domain.Message
+		systemDomains []*domain.SystemDomain
+		userDomains   []*domain.UserDomain
+		users         []domain.User
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(aggregationConcurrency)
+	g.Go(func() (err error) {
+		byMailbox, err = listMessagesBounded(gctx, s.store, mailboxes)
+		return err
+	})
+	g.Go(func() (err error) {
+		systemDomains, err = s.store.ListSystemDomains(ctx)
+		return err
+	})
+	g.Go(func() (err error) {
+		userDomains, err = s.store.ListAllUserDomains(ctx)
+		return err
+	})
+	g.Go(func() (err error) {
+		users, err = s.listUsers(ctx)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	report := &RetentionReport{GeneratedAt: now}
+
+	// 邮箱：过期后由每小时的清理任务删除
+	mailboxCat := newRetentionCategory(RetentionCategoryMailboxes, "deleted within 1h after expiry")
+	overdueMailboxes := make(map[string]bool)
+	for _, mb := range mailboxes {
+		overdue := mb.ExpiresAt != nil && now.After(mb.ExpiresAt.Add(RetentionSweepGrace))
+		if overdue {
+			overdueMailboxes[mb.ID] = true
+		}
+		mailboxCat.add(mb.CreatedAt, now, overdue)
+	}
+	report.Categories = append(report.Categories, mailboxCat.finish(true))
+
+	// 邮件：随邮箱删除；公开收件箱中的邮件另按固定时长删除
+	messageCat := newRetentionCategory(RetentionCategoryMessages, "deleted with their mailbox; public inbox messages after 1h")
+	for _, mb := range mailboxes {
+		for _, msg := range byMailbox[mb.ID] {
+			overdue := overdueMailboxes[mb.ID] ||
+				(mb.IsPublic && now.After(msg.ReceivedAt.Add(PublicInboxRetention+RetentionSweepGrace)))
+			messageCat.add(msg.ReceivedAt, now, overdue)
+		}
+	}
+	report.Categories = append(report.Categories, messageCat.finish(true))
+
+	// 未验证的系统域名：创建 24 小时后删除
+	domainCat := newRetentionCategory(RetentionCategorySystemDomains, "deleted 24h after creation if unverified")
+	for _, d := range systemDomains {
+		if d.Status == domain.SystemDomainStatusVerified {
+			continue
+		}
+		domainCat.add(d.CreatedAt, now, now.After(d.CreatedAt.Add(UnverifiedDomainRetention+RetentionSweepGrace)))
+	}
+	report.Categories = append(report.Categories, domainCat.finish(true))
+
+	// 用户域名和用户：没有自动删除策略
+	userDomainCat := newRetentionCategory(RetentionCategoryUserDomains, "retained until deleted by the owner")
+	for _, d := range userDomains {
+		userDomainCat.add(d.CreatedAt, now, false)
+	}
+	report.Categories = append(report.Categories, userDomainCat.finish(false))
+
+	userCat := newRetentionCategory(RetentionCategoryUsers, "retained until the account is deleted")
+	for _, u := range users {
+		userCat.add(u.CreatedAt, now, false)
+	}
+	report.Categories = append(report.Categories, userCat.finish(false))
+
+	if s.audit != nil {
+		s.audit(AuditRetentionReport, actorID, "")
+	}
+	return report, nil
+}
+
+// retainedMailboxes 存储中仍保留的全部邮箱（包括已过期但尚未清理的）
+func (s *RetentionService) retainedMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error) {
+	expired, err := s.store.ListExpiredMailboxes(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(expired))
+	mailboxes := make([]domain.Mailbox, 0, len(expired))
+	for _, mb := range expired {
+		seen[mb.ID] = true
+		mailboxes = append(mailboxes, mb)
+	}
+	for _, mb := range s.store.ListMailboxes(ctx) {
+		if !seen[mb.ID] {
+			seen[mb.ID] = true
+			mailboxes = append(mailboxes, mb)
+		}
+	}
+	return mailboxes, nil
+}
+
+// listUsers 分页读取全部用户
+func (s *RetentionService) listUsers(ctx context.Context) ([]domain.User, error) {
+	var all []domain.User
+	for page := 1; ; page++ {
+		users, total, err := s.store.ListUsers(ctx, page, retentionUserPageSize, "", nil, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, users...)
+		if len(users) < retentionUserPageSize || len(all) >= total {
+			return all, nil
+		}
+	}
+}
+
+// retentionCategoryBuilder 累计单个类别的统计
+type retentionCategoryBuilder struct {
+	category RetentionCategory
+}
+
+func newRetentionCategory(name, policy string) *retentionCategoryBuilder {
+	buckets := make([]RetentionBucket, len(retentionBuckets))
+	for i, b := range retentionBuckets {
+		buckets[i].Age = b.label
+	}
+	return &retentionCategoryBuilder{category: RetentionCategory{Category: name, Policy: policy, Buckets: buckets}}
+}
+
+// add 计入一条记录（created 为记录的创建/接收时间）
+func (b *retentionCategoryBuilder) add(created, now time.Time, overdue bool) {
+	c := &b.category
+	c.Total++
+	if c.Oldest == nil || created.Before(*c.Oldest) {
+		oldest := created
+		c.Oldest = &oldest
+	}
+	age := now.Sub(created)
+	for i, bucket := range retentionBuckets {
+		if bucket.upper == 0 || age < bucket.upper {
+			c.Buckets[i].Count++
+			break
+		}
+	}
+	if overdue {
+		c.Overdue++
+	}
+}
+
+// finish 结束统计；enforced 表示类别有自动删除策略
+func (b *retentionCategoryBuilder) finish(enforced bool) RetentionCategory {
+	if enforced {
+		satisfied := b.category.Overdue == 0
+		b.category.Satisfied = &satisfied
+	}
+	return b.category
+}
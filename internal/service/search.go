@@ -1,14 +1,24 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/htmltext"
+	"tempmail/backend/internal/storage"
+)
+
+var (
+	ErrSearchIndexUnavailable = errors.New("full-text search index is not configured")
+	ErrSearchQueryRequired    = errors.New("search query is required")
 )
 
 // SearchService 搜索服务
 type SearchService struct {
 	store domain.Store
+	index storage.SearchIndexRepository // 全文索引（可选，跨邮箱搜索使用）
 }
 
 // NewSearchService 创建搜索服务
@@ -18,18 +28,69 @@ func NewSearchService(store domain.Store) *SearchService {
 	}
 }
 
+// SetIndex 设置全文索引
+func (s *SearchService) SetIndex(index storage.SearchIndexRepository) {
+	s.index = index
+}
+
+// SearchAllInput 跨邮箱全文搜索输入
+type SearchAllInput struct {
+	UserID   string // 当前用户（必填），只搜索其名下的邮箱
+	Query    string // 搜索关键词，所有词都需命中
+	Page     int    // 页码
+	PageSize int    // 每页数量
+	// Highlight 高亮选项，nil 表示不返回摘要
+	Highlight *domain.HighlightOptions
+}
+
+// SearchAll 通过全文索引在用户的所有邮箱中搜索邮件（含正文），按接收时间倒序
+func (s *SearchService) SearchAll(ctx context.Context, input SearchAllInput) (*domain.MessageSearchResult, error) {
+	if s.index == nil {
+		return nil, ErrSearchIndexUnavailable
+	}
+	terms := domain.IndexTerms(input.Query)
+	if len(terms) == 0 {
+		return nil, ErrSearchQueryRequired
+	}
+
+	mailboxes := s.store.ListMailboxesByUserID(ctx, input.UserID)
+	mailboxIDs := make([]string, 0, len(mailboxes))
+	for _, mb := range mailboxes {
+		mailboxIDs = append(mailboxIDs, mb.ID)
+	}
+
+	result, err := s.index.SearchIndex(ctx, domain.IndexQuery{
+		MailboxIDs: mailboxIDs,
+		Terms:      terms,
+		Page:       input.Page,
+		PageSize:   input.PageSize,
+	})
+	if err != nil || input.Highlight == nil {
+		return result, err
+	}
+	attachSnippets(result, input.Query, *input.Highlight)
+	return result, nil
+}
+
 // SearchMessagesInput 搜索邮件输入
 type SearchMessagesInput struct {
-	MailboxID     string     // 邮箱ID（必填）
-	Query         string     // 搜索关键词
-	From          string     // 发件人筛选
-	Subject       string     // 主题筛选
-	StartDate     *time.Time // 开始日期
-	EndDate       *time.Time // 结束日期
-	IsRead        *bool      // 是否已读
-	HasAttachment *bool      // 是否有附件
-	Page          int        // 页码
-	PageSize      int        // 每页数量
+	MailboxID     string            // 邮箱ID（必填）
+	Query         string            // 搜索关键词
+	From          string            // 发件人筛选
+	Subject       string            // 主题筛选
+	StartDate     *time.Time        // 开始日期
+	EndDate       *time.Time        // 结束日期
+	IsRead        *bool             // 是否已读
+	HasAttachment *bool             // 是否有附件
+	Language      string            // 正文语言（ISO 639-1）
+	SpamScoreGte  *float64          // 垃圾邮件评分下限（含）
+	IsSpam        *bool             // 是否判定为垃圾邮件
+	DKIMResult    string            // DKIM 验证结果（pass、fail 或 none）
+	Headers       map[string]string // 保存的头精确匹配（header.<名称>=<值>）
+	Page          int               // 页码
+	PageSize      int               // 每页数量
+	// Highlight 高亮选项，nil 表示不返回摘要
+	Highlight *domain.HighlightOptions
 }
 
 // SearchMessages 搜索邮件
@@ -40,7 +101,7 @@ type SearchMessagesInput struct {
 // 返回值:
 //   - *domain.MessageSearchResult: 搜索结果
 //   - error: 错误信息
-func (s *SearchService) SearchMessages(input SearchMessagesInput) (*domain.MessageSearchResult, error) {
+func (s *SearchService) SearchMessages(ctx context.Context, input SearchMessagesInput) (*domain.MessageSearchResult, error) {
 	// 构建搜索条件
 	criteria := domain.MessageSearchCriteria{
 		MailboxID:     input.MailboxID,
@@ -51,10 +112,58 @@ func (s *SearchService) SearchMessages(input SearchMessagesInput) (*domain.Messa
 		EndDate:       input.EndDate,
 		IsRead:        input.IsRead,
 		HasAttachment: input.HasAttachment,
+		Language:      input.Language,
+		SpamScoreGte:  input.SpamScoreGte,
+		IsSpam:        input.IsSpam,
+		DKIMResult:    input.DKIMResult,
+		Headers:       input.Headers,
 		Page:          input.Page,
 		PageSize:      input.PageSize,
+		Highlight:     input.Highlight,
 	}
 
 	// 执行搜索
-	return s.store.SearchMessages(criteria)
+	result, err := s.store.SearchMessages(ctx, criteria)
+	if err != nil || result == nil || input.Highlight == nil {
+		return result, err
+	}
+
+	attachSnippets(result, input.Query, *input.Highlight)
+	return result, nil
+}
+
+// attachSnippets 为存储层未生成摘要的字段补全摘要
+//
+// PostgreSQL 路径已通过 ts_headline 生成主题和发件人摘要，此处只补缺；
+// 内存和 LIKE 路径完全由 Go 提取器生成。
+func attachSnippets(result *domain.MessageSearchResult, query string, opts domain.HighlightOptions) {
+	terms := domain.SearchTerms(query)
+	if len(terms) == 0 {
+		return
+	}
+	if result.Snippets == nil {
+		result.Snippets = make(map[string]domain.MessageSnippets, len(result.Messages))
+	}
+
+	for _, msg := range result.Messages {
+		snippets := result.Snippets[msg.ID]
+		if snippets.Subject == "" {
+			snippets.Subject = domain.BuildSnippet(msg.Subject, terms, opts)
+		}
+		if snippets.Text == "" {
+			text := msg.Text
+			if text == "" && msg.HTML != "" {
+				text = htmltext.Render(msg.HTML) // 旧的纯 HTML 邮件临时转换
+			}
+			snippets.Text = domain.BuildSnippet(text, terms, opts)
+		}
+		if snippets.From == "" {
+			snippets.From = domain.BuildSnippet(msg.From, terms, opts)
+		}
+		if snippets.IsEmpty() {
+			delete(result.Snippets, msg.ID)
+			continue
+		}
+		result.Snippets[msg.ID] = snippets
+	}
 }
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestBuildSnippet(t *testing.T) {
+	opts := domain.DefaultHighlightOptions()
+
+	t.Run("中文主题高亮且不截断多字节字符", func(t *testing.T) {
+		subject := strings.Repeat("临时邮箱服务", 30) + "验证码" + strings.Repeat("欢迎使用", 30)
+		snippet := domain.BuildSnippet(subject, domain.SearchTerms("验证码"), opts)
+
+		assert.Contains(t, snippet, "<em>验证码</em>")
+		assert.True(t, utf8.ValidString(snippet))
+		assert.True(t, strings.HasPrefix(snippet, "…"))
+		assert.True(t, strings.HasSuffix(snippet, "…"))
+	})
+
+	t.Run("多关键词不区分大小写", func(t *testing.T) {
+		snippet := domain.BuildSnippet("Your Login CODE is 123456", domain.SearchTerms("code login"), opts)
+		assert.Equal(t, "Your <em>Login</em> <em>CODE</em> is 123456", snippet)
+	})
+
+	t.Run("原文中的HTML被转义", func(t *testing.T) {
+		snippet := domain.BuildSnippet(`<script>alert("x")</script> reset link`, domain.SearchTerms("reset"), opts)
+		assert.Equal(t, "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <em>reset</em> link", snippet)
+	})
+
+	t.Run("自定义标记", func(t *testing.T) {
+		snippet := domain.BuildSnippet("hello world", []string{"world"}, domain.HighlightOptions{PreTag: "[", PostTag: "]"})
+		assert.Equal(t, "hello [world]", snippet)
+	})
+
+	t.Run("SQL与Go路径标记格式一致", func(t *testing.T) {
+		// 模拟 ts_headline 使用哨兵字符输出的结果
+		sqlRaw := "Your " + domain.SnippetStartSentinel + "code" + domain.SnippetStopSentinel + " & more"
+		fromSQL := domain.FinalizeSnippet(sqlRaw, opts)
+		fromGo := domain.BuildSnippet("Your code & more", []string{"code"}, opts)
+		assert.Equal(t, fromGo, fromSQL)
+		assert.Equal(t, "Your <em>code</em> &amp; more", fromSQL)
+	})
+}
+
+func TestParseHighlightOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		pre, post string
+		want      domain.HighlightOptions
+	}{
+		{"都为空时使用默认", "", "", domain.DefaultHighlightOptions()},
+		{"白名单标签", "<mark>", "</mark>", domain.HighlightOptions{PreTag: "<mark>", PostTag: "</mark>"}},
+		{"不区分大小写", "<STRONG>", "</Strong>", domain.HighlightOptions{PreTag: "<strong>", PostTag: "</strong>"}},
+		{"缺少后标记时按前标记配对", "<b>", "", domain.HighlightOptions{PreTag: "<b>", PostTag: "</b>"}},
+		{"缺少前标记时按后标记配对", "", "</span>", domain.HighlightOptions{PreTag: "<span>", PostTag: "</span>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := domain.ParseHighlightOptions(tt.pre, tt.post)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, opts)
+		})
+	}
+
+	for _, invalid := range [][2]string{
+		{`<img src=x onerror=alert(1)>`, ""},
+		{`<span onmouseover="alert(1)">`, "</span>"},
+		{"<script>", "</script>"},
+		{"<mark>", "</em>"},
+		{"[", "]"},
+		{"", "<em>"},
+	} {
+		_, err := domain.ParseHighlightOptions(invalid[0], invalid[1])
+		assert.ErrorIs(t, err, domain.ErrInvalidHighlightTag, "%q %q", invalid[0], invalid[1])
+	}
+}
+
+func TestSearchService_Snippets(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	mailbox := &domain.Mailbox{ID: "mb-1", Address: "a@temp.mail", LocalPart: "a", Domain: "temp.mail", CreatedAt: time.Now()}
+	require.NoError(t, store.SaveMailbox(t.Context(), mailbox))
+	require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+		ID:        "msg-1",
+		MailboxID: "mb-1",
+		From:      "noreply@example.com",
+		Subject:   "欢迎注册",
+		Text:      "您的验证码是 <b>482913</b>，十分钟内有效。",
+		CreatedAt: time.Now(),
+	}))
+	svc := NewSearchService(store)
+
+	t.Run("仅正文命中时只返回正文摘要", func(t *testing.T) {
+		opts := domain.DefaultHighlightOptions()
+		result, err := svc.SearchMessages(t.Context(), SearchMessagesInput{MailboxID: "mb-1", Query: "验证码", Highlight: &opts})
+		require.NoError(t, err)
+		require.Len(t, result.Messages, 1)
+
+		snippets := result.Snippets["msg-1"]
+		assert.Empty(t, snippets.Subject)
+		assert.Empty(t, snippets.From)
+		assert.Equal(t, "您的<em>验证码</em>是 &lt;b&gt;482913&lt;/b&gt;，十分钟内有效。", snippets.Text)
+	})
+
+	t.Run("关闭高亮时省略摘要", func(t *testing.T) {
+		result, err := svc.SearchMessages(t.Context(), SearchMessagesInput{MailboxID: "mb-1", Query: "验证码"})
+		require.NoError(t, err)
+		require.Len(t, result.Messages, 1)
+		assert.Nil(t, result.Snippets)
+	})
+}
+
+func TestSearchService_DKIMResult(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-1", Address: "a@temp.mail", LocalPart: "a", Domain: "temp.mail", CreatedAt: time.Now()}))
+	for id, result := range map[string]string{"signed": domain.DKIMResultPass, "spoofed": domain.DKIMResultFail, "legacy": ""} {
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+			ID: id, MailboxID: "mb-1", From: "billing@bank.example", Subject: id, DKIMResult: result, CreatedAt: time.Now(),
+		}))
+	}
+	svc := NewSearchService(store)
+
+	result, err := svc.SearchMessages(t.Context(), SearchMessagesInput{MailboxID: "mb-1", DKIMResult: domain.DKIMResultFail})
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, "spoofed", result.Messages[0].ID)
+
+	result, err = svc.SearchMessages(t.Context(), SearchMessagesInput{MailboxID: "mb-1"})
+	require.NoError(t, err)
+	assert.Len(t, result.Messages, 3)
+}
+
+func TestHTMLOnlyMessages(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-1", Address: "a@temp.mail", CreatedAt: time.Now()}))
+	messages := NewMessageService(store)
+	search := NewSearchService(store)
+	html := `<html><head><style>p{color:red}</style></head><body>
+		<table><tr><td><p>Your verification code is <b>482913</b>.</p></td></tr></table>
+		<p><a href="https://accounts.example.com/verify">Verify your email</a></p></body></html>`
+
+	t.Run("入库时由 HTML 生成纯文本并用于预览", func(t *testing.T) {
+		message, err := messages.Create(t.Context(), CreateMessageInput{MailboxID: "mb-1", From: "no-reply@example.com", Subject: "Welcome", HTML: html})
+		require.NoError(t, err)
+		assert.True(t, message.TextDerivedFromHTML)
+		assert.True(t, message.HasText)
+		assert.Contains(t, message.Text, "Your verification code is 482913.")
+		assert.Contains(t, message.Text, "Verify your email (https://accounts.example.com/verify)")
+		assert.Equal(t, "en", message.DetectedLanguage)
+		assert.True(t, strings.HasPrefix(Preview(message), "Your verification code is 482913."))
+
+		plain, err := messages.Create(t.Context(), CreateMessageInput{MailboxID: "mb-1", Subject: "plain", Text: "hello", HTML: "<p>hello</p>"})
+		require.NoError(t, err)
+		assert.False(t, plain.TextDerivedFromHTML, "已有纯文本时不替换")
+		assert.Equal(t, "hello", plain.Text)
+	})
+
+	t.Run("搜索命中生成的纯文本", func(t *testing.T) {
+		opts := domain.DefaultHighlightOptions()
+		result, err := search.SearchMessages(t.Context(), SearchMessagesInput{MailboxID: "mb-1", Query: "482913", Highlight: &opts})
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Total)
+		assert.Contains(t, result.Snippets[result.Messages[0].ID].Text, "<em>482913</em>")
+	})
+
+	t.Run("旧邮件在搜索时临时转换", func(t *testing.T) {
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+			ID: "legacy", MailboxID: "mb-1", Subject: "old", HTML: "<div>Invoice <i>INV-2024-77</i> is ready</div>", HasHTML: true,
+		}))
+		opts := domain.DefaultHighlightOptions()
+		result, err := search.SearchMessages(t.Context(), SearchMessagesInput{MailboxID: "mb-1", Query: "inv-2024-77", Highlight: &opts})
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Total)
+		assert.Contains(t, result.Snippets["legacy"].Text, "<em>INV-2024-77</em>")
+
+		stored, err := store.GetMessage(t.Context(), "mb-1", "legacy")
+		require.NoError(t, err)
+		assert.Empty(t, stored.Text, "不回写")
+	})
+}
+
+func TestSearchService_SearchAll(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	owner, other := "user-1", "user-2"
+	for id, userID := range map[string]*string{"mb-1": &owner, "mb-2": &owner, "mb-3": &other} {
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: id, Address: id + "@temp.mail", UserID: userID, CreatedAt: time.Now()}))
+	}
+	messages := NewMessageService(store)
+	messages.SetSearchIndex(store)
+	search := NewSearchService(store)
+	search.SetIndex(store)
+
+	create := func(input CreateMessageInput) *domain.Message {
+		t.Helper()
+		message, err := messages.Create(t.Context(), input)
+		require.NoError(t, err)
+		return message
+	}
+	invoice := create(CreateMessageInput{MailboxID: "mb-1", Subject: "Invoice", Text: "Your invoice #4711 is ready", Received: time.Now().Add(-time.Hour)})
+	htmlOnly := create(CreateMessageInput{MailboxID: "mb-2", Subject: "Receipt", HTML: "<p>Invoice <b>4711</b> paid</p>"})
+	create(CreateMessageInput{MailboxID: "mb-3", Subject: "Invoice", Text: "invoice 4711 for someone else"})
+	create(CreateMessageInput{MailboxID: "mb-1", Subject: "Invoice", Text: "invoice 4711 suspicious", Quarantined: true})
+	cjk := create(CreateMessageInput{MailboxID: "mb-2", Subject: "通知", Text: "您的验证码已发送"})
+
+	searchAll := func(query string) *domain.MessageSearchResult {
+		t.Helper()
+		result, err := search.SearchAll(t.Context(), SearchAllInput{UserID: owner, Query: query})
+		require.NoError(t, err)
+		return result
+	}
+	ids := func(result *domain.MessageSearchResult) []string {
+		var ids []string
+		for _, msg := range result.Messages {
+			ids = append(ids, msg.ID)
+		}
+		return ids
+	}
+
+	t.Run("跨邮箱命中正文，按接收时间倒序", func(t *testing.T) {
+		assert.Equal(t, []string{htmlOnly.ID, invoice.ID}, ids(searchAll("INVOICE 4711")))
+	})
+
+	t.Run("所有关键词都需命中", func(t *testing.T) {
+		assert.Equal(t, []string{invoice.ID}, ids(searchAll("invoice ready")))
+		assert.Empty(t, searchAll("invoice missing").Messages)
+	})
+
+	t.Run("中文逐字匹配", func(t *testing.T) {
+		assert.Equal(t, []string{cjk.ID}, ids(searchAll("验证码")))
+	})
+
+	t.Run("返回命中摘要", func(t *testing.T) {
+		opts := domain.DefaultHighlightOptions()
+		result, err := search.SearchAll(t.Context(), SearchAllInput{UserID: owner, Query: "ready", Highlight: &opts})
+		require.NoError(t, err)
+		assert.Contains(t, result.Snippets[invoice.ID].Text, "<em>ready</em>")
+	})
+
+	t.Run("删除的邮件从索引中移除", func(t *testing.T) {
+		require.NoError(t, messages.Delete(t.Context(), "mb-1", invoice.ID))
+		assert.Equal(t, []string{htmlOnly.ID}, ids(searchAll("4711")))
+	})
+
+	t.Run("缺少关键词或未配置索引", func(t *testing.T) {
+		_, err := search.SearchAll(t.Context(), SearchAllInput{UserID: owner, Query: " , "})
+		assert.ErrorIs(t, err, ErrSearchQueryRequired)
+		_, err = NewSearchService(store).SearchAll(t.Context(), SearchAllInput{UserID: owner, Query: "invoice"})
+		assert.ErrorIs(t, err, ErrSearchIndexUnavailable)
+	})
+}
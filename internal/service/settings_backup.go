@@ -0,0 +1,617 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+var (
+	ErrBackupSchemaMismatch  = errors.New("unsupported settings backup schema")
+	ErrBackupVersionMismatch = errors.New("unsupported settings backup version")
+	ErrInvalidBackup         = errors.New("invalid settings backup")
+	ErrRestoreConflicts      = errors.New("settings restore has conflicts")
+)
+
+const (
+	// SettingsBackupSchema 配置备份文档标识
+	SettingsBackupSchema = "tempmail.settings-backup"
+	// SettingsBackupVersion 配置备份文档版本（结构不兼容变更时递增）
+	SettingsBackupVersion = 1
+)
+
+// SettingsBackup 服务器配置备份文档
+//
+// 只包含重建实例所需的配置：系统配置、系统域名（含验证状态与令牌）和 Webhook 设置。
+// 用户、邮件、API Key 哈希和 JWT 密钥不在备份范围内；Webhook 密钥仅在显式要求时导出。
+// 内容过滤规则和告警规则目前由代码定义，配额覆盖尚未持久化，因此暂不包含。
+type SettingsBackup struct {
+	Schema          string               `json:"schema"`
+	Version         int                  `json:"version"`
+	ExportedAt      time.Time            `json:"exportedAt"`
+	IncludesSecrets bool                 `json:"includesSecrets"`
+	SystemConfig    *BackupSystemConfig  `json:"systemConfig,omitempty"`
+	SystemDomains   []BackupSystemDomain `json:"systemDomains"`
+	Webhooks        []BackupWebhook      `json:"webhooks"`
+}
+
+// BackupSystemConfig 系统配置（不含维护模式等运行时状态）
+type BackupSystemConfig struct {
+	SMTP      domain.SMTPConfig      `json:"smtp"`
+	Mailbox   domain.MailboxConfig   `json:"mailbox"`
+	RateLimit domain.RateLimitConfig `json:"rateLimit"`
+	Security  domain.SecurityConfig  `json:"security"`
+}
+
+// BackupSystemDomain 系统域名（按域名匹配，不含 ID 和邮箱计数）
+type BackupSystemDomain struct {
+	Domain       string                    `json:"domain"`
+	Status       domain.SystemDomainStatus `json:"status"`
+	VerifyToken  string                    `json:"verifyToken"`
+	VerifyMethod string                    `json:"verifyMethod"`
+	VerifiedAt   *time.Time                `json:"verifiedAt,omitempty"`
+	IsActive     bool                      `json:"isActive"`
+	IsDefault    bool                      `json:"isDefault"`
+	MXRecords    []string                  `json:"mxRecords,omitempty"`
+	Notes        string                    `json:"notes,omitempty"`
+	CreatedAt    time.Time                 `json:"createdAt"`
+}
+
+// BackupWebhook Webhook 设置（按 ID 匹配）
+type BackupWebhook struct {
+	ID       string   `json:"id"`
+	UserID   string   `json:"userId"`
+	URL      string   `json:"url"`
+	Events   []string `json:"events"`
+	IsActive bool     `json:"isActive"`
+	Secret   string   `json:"secret,omitempty"` // 仅 includeSecrets 时导出
+}
+
+// RestoreAction 恢复动作
+type RestoreAction string
+
+const (
+	RestoreActionCreate    RestoreAction = "create"
+	RestoreActionUpdate    RestoreAction = "update"
+	RestoreActionUnchanged RestoreAction = "unchanged"
+	RestoreActionConflict  RestoreAction = "conflict"
+)
+
+// 恢复项类型
+const (
+	RestoreKindSystemConfig = "system_config"
+	RestoreKindSystemDomain = "system_domain"
+	RestoreKindWebhook      = "webhook"
+)
+
+// RestoreItem 单个配置项的恢复结果
+type RestoreItem struct {
+	Kind   string        `json:"kind"`
+	Key    string        `json:"key"` // 域名 / Webhook ID / system
+	Action RestoreAction `json:"action"`
+	Reason string        `json:"reason,omitempty"`
+}
+
+// RestoreReport 恢复报告
+type RestoreReport struct {
+	DryRun    bool          `json:"dryRun"`
+	Applied   bool          `json:"applied"`
+	Created   int           `json:"created"`
+	Updated   int           `json:"updated"`
+	Unchanged int           `json:"unchanged"`
+	Conflicts int           `json:"conflicts"`
+	Items     []RestoreItem `json:"items"`
+}
+
+// SettingsAuditFunc 审计回调（恢复时每项实际变更调用一次）
+type SettingsAuditFunc func(actorID string, item RestoreItem)
+
+// settingsTransactor 支持事务的存储（恢复时整体提交或回滚）
+type settingsTransactor interface {
+	WithTransaction(fn func(tx storage.Store) error) error
+}
+
+// systemDomainUpdater 支持更新系统域名的存储（数据库存储的 SaveSystemDomain 只能插入）
+type systemDomainUpdater interface {
+	UpdateSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error
+}
+
+// SettingsBackupService 配置导出/恢复服务
+type SettingsBackupService struct {
+	store storage.Store
+	audit SettingsAuditFunc
+}
+
+// NewSettingsBackupService 创建配置导出/恢复服务
+func NewSettingsBackupService(store storage.Store) *SettingsBackupService {
+	return &SettingsBackupService{store: store}
+}
+
+// SetAuditFunc 设置审计回调
+func (s *SettingsBackupService) SetAuditFunc(fn SettingsAuditFunc) {
+	s.audit = fn
+}
+
+// Export 导出配置备份
+func (s *SettingsBackupService) Export(ctx context.Context, includeSecrets bool) (*SettingsBackup, error) {
+	backup := &SettingsBackup{
+		Schema:          SettingsBackupSchema,
+		Version:         SettingsBackupVersion,
+		ExportedAt:      time.Now().UTC(),
+		IncludesSecrets: includeSecrets,
+		SystemDomains:   []BackupSystemDomain{},
+		Webhooks:        []BackupWebhook{},
+	}
+
+	config, err := s.store.GetSystemConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	backup.SystemConfig = &BackupSystemConfig{
+		SMTP:      config.SMTP,
+		Mailbox:   config.Mailbox,
+		RateLimit: config.RateLimit,
+		Security:  config.Security,
+	}
+
+	sysDomains, err := s.store.ListSystemDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range sysDomains {
+		backup.SystemDomains = append(backup.SystemDomains, backupSystemDomain(d))
+	}
+	sort.Slice(backup.SystemDomains, func(i, j int) bool {
+		return backup.SystemDomains[i].Domain < backup.SystemDomains[j].Domain
+	})
+
+	webhooks, err := s.listAllWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range webhooks {
+		item := BackupWebhook{
+			ID:       w.ID,
+			UserID:   w.UserID,
+			URL:      w.URL,
+			Events:   append([]string(nil), w.Events...),
+			IsActive: w.IsActive,
+		}
+		if includeSecrets {
+			item.Secret = w.Secret
+		}
+		backup.Webhooks = append(backup.Webhooks, item)
+	}
+	sort.Slice(backup.Webhooks, func(i, j int) bool {
+		return backup.Webhooks[i].ID < backup.Webhooks[j].ID
+	})
+
+	return backup, nil
+}
+
+// Restore 恢复配置备份
+//
+// dryRun 时只返回将要创建/更新/冲突的项；否则在存储支持时于单个事务内幂等写入
+// （系统域名按域名匹配，Webhook 按 ID 匹配）。存在冲突时拒绝写入并返回 ErrRestoreConflicts。
+// 备份中没有的现有配置保持不变。
+func (s *SettingsBackupService) Restore(ctx context.Context, backup *SettingsBackup, dryRun bool, actorID string) (*RestoreReport, error) {
+	if err := validateSettingsBackup(backup); err != nil {
+		return nil, err
+	}
+
+	plan, err := s.planRestore(ctx, s.store, backup, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := plan.report(dryRun)
+	if dryRun {
+		return report, nil
+	}
+	if report.Conflicts > 0 {
+		return report, ErrRestoreConflicts
+	}
+
+	apply := func(store storage.Store) error {
+		// 事务内重新生成计划，保证基于事务中看到的数据写入
+		plan, err := s.planRestore(ctx, store, backup, actorID)
+		if err != nil {
+			return err
+		}
+		report = plan.report(false)
+		if report.Conflicts > 0 {
+			return ErrRestoreConflicts
+		}
+		for _, op := range plan.ops {
+			if op.apply == nil {
+				continue
+			}
+			if err := op.apply(store); err != nil {
+				return fmt.Errorf("restore %s %s: %w", op.item.Kind, op.item.Key, err)
+			}
+		}
+		return nil
+	}
+
+	if tx, ok := s.store.(settingsTransactor); ok {
+		err = tx.WithTransaction(apply)
+	} else {
+		err = apply(s.store)
+	}
+	if errors.Is(err, ErrRestoreConflicts) {
+		return report, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report.Applied = true
+	if s.audit != nil {
+		for _, item := range report.Items {
+			if item.Action == RestoreActionCreate || item.Action == RestoreActionUpdate {
+				s.audit(actorID, item)
+			}
+		}
+	}
+	return report, nil
+}
+
+// restoreOp 恢复计划中的一项
+type restoreOp struct {
+	item  RestoreItem
+	apply func(store storage.Store) error
+}
+
+// restorePlan 恢复计划
+type restorePlan struct {
+	ops []restoreOp
+}
+
+func (p *restorePlan) add(item RestoreItem, apply func(store storage.Store) error) {
+	p.ops = append(p.ops, restoreOp{item: item, apply: apply})
+}
+
+func (p *restorePlan) report(dryRun bool) *RestoreReport {
+	report := &RestoreReport{DryRun: dryRun, Items: make([]RestoreItem, 0, len(p.ops))}
+	for _, op := range p.ops {
+		report.Items = append(report.Items, op.item)
+		switch op.item.Action {
+		case RestoreActionCreate:
+			report.Created++
+		case RestoreActionUpdate:
+			report.Updated++
+		case RestoreActionUnchanged:
+			report.Unchanged++
+		case RestoreActionConflict:
+			report.Conflicts++
+		}
+	}
+	return report
+}
+
+// planRestore 对比备份与当前数据，生成恢复计划
+func (s *SettingsBackupService) planRestore(ctx context.Context, store storage.Store, backup *SettingsBackup, actorID string) (*restorePlan, error) {
+	plan := &restorePlan{}
+
+	if err := planSystemConfig(ctx, plan, store, backup.SystemConfig, actorID); err != nil {
+		return nil, err
+	}
+	if err := planSystemDomains(ctx, plan, store, backup.SystemDomains, actorID); err != nil {
+		return nil, err
+	}
+	if err := planWebhooks(ctx, plan, store, backup.Webhooks); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func planSystemConfig(ctx context.Context, plan *restorePlan, store storage.Store, cfg *BackupSystemConfig, actorID string) error {
+	if cfg == nil {
+		return nil
+	}
+	current, err := store.GetSystemConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	item := RestoreItem{Kind: RestoreKindSystemConfig, Key: "system", Action: RestoreActionUnchanged}
+	existing := BackupSystemConfig{SMTP: current.SMTP, Mailbox: current.Mailbox, RateLimit: current.RateLimit, Security: current.Security}
+	if reflect.DeepEqual(existing, *cfg) {
+		plan.add(item, nil)
+		return nil
+	}
+
+	item.Action = RestoreActionUpdate
+	plan.add(item, func(store storage.Store) error {
+		config, err := store.GetSystemConfig(ctx)
+		if err != nil {
+			return err
+		}
+		config.SMTP = cfg.SMTP
+		config.Mailbox = cfg.Mailbox
+		config.RateLimit = cfg.RateLimit
+		config.Security = cfg.Security
+		config.UpdatedAt = time.Now()
+		config.UpdatedBy = actorID
+		return store.SaveSystemConfig(ctx, config)
+	})
+	return nil
+}
+
+func planSystemDomains(ctx context.Context, plan *restorePlan, store storage.Store, domains []BackupSystemDomain, actorID string) error {
+	existing, err := store.ListSystemDomains(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*domain.SystemDomain, len(existing))
+	for _, d := range existing {
+		byName[strings.ToLower(d.Domain)] = d
+	}
+
+	inBackup := make(map[string]bool, len(domains))
+	hasDefault := false
+	for _, b := range domains {
+		b := b
+		name := strings.ToLower(strings.TrimSpace(b.Domain))
+		b.Domain = name
+		inBackup[name] = true
+		hasDefault = hasDefault || b.IsDefault
+
+		item := RestoreItem{Kind: RestoreKindSystemDomain, Key: name}
+		current, ok := byName[name]
+		if !ok {
+			// 同名用户域名存在时无法创建系统域名
+			if userDomain, err := store.GetUserDomainByDomain(ctx, name); err == nil && userDomain != nil {
+				item.Action = RestoreActionConflict
+				item.Reason = "domain is registered as a user domain"
+				plan.add(item, nil)
+				continue
+			}
+			item.Action = RestoreActionCreate
+			plan.add(item, func(store storage.Store) error {
+				sysDomain := &domain.SystemDomain{ID: uuid.New().String(), CreatedBy: actorID}
+				applyBackupSystemDomain(sysDomain, b)
+				return store.SaveSystemDomain(ctx, sysDomain)
+			})
+			continue
+		}
+
+		if sameBackupSystemDomain(backupSystemDomain(current), b) {
+			item.Action = RestoreActionUnchanged
+			plan.add(item, nil)
+			continue
+		}
+		item.Action = RestoreActionUpdate
+		id := current.ID
+		plan.add(item, func(store storage.Store) error {
+			return updateSystemDomain(ctx, store, id, func(d *domain.SystemDomain) {
+				applyBackupSystemDomain(d, b)
+			})
+		})
+	}
+
+	// 备份指定了默认域名时，取消备份之外的其他默认域名
+	if hasDefault {
+		for _, d := range existing {
+			name := strings.ToLower(d.Domain)
+			if inBackup[name] || !d.IsDefault {
+				continue
+			}
+			id := d.ID
+			plan.add(RestoreItem{
+				Kind:   RestoreKindSystemDomain,
+				Key:    name,
+				Action: RestoreActionUpdate,
+				Reason: "default domain replaced by backup",
+			}, func(store storage.Store) error {
+				return updateSystemDomain(ctx, store, id, func(d *domain.SystemDomain) {
+					d.IsDefault = false
+				})
+			})
+		}
+	}
+	return nil
+}
+
+func planWebhooks(ctx context.Context, plan *restorePlan, store storage.Store, webhooks []BackupWebhook) error {
+	for _, b := range webhooks {
+		b := b
+		item := RestoreItem{Kind: RestoreKindWebhook, Key: b.ID}
+
+		if _, err := store.GetUserByID(ctx, b.UserID); err != nil {
+			item.Action = RestoreActionConflict
+			item.Reason = "owner user not found"
+			plan.add(item, nil)
+			continue
+		}
+
+		current, err := store.GetWebhook(ctx, b.ID)
+		if err != nil {
+			item.Action = RestoreActionCreate
+			if b.Secret == "" {
+				item.Reason = "secret not included, a new secret will be generated"
+			}
+			plan.add(item, func(store storage.Store) error {
+				webhook := &domain.Webhook{
+					ID:       b.ID,
+					UserID:   b.UserID,
+					URL:      b.URL,
+					Events:   append([]string(nil), b.Events...),
+					Secret:   b.Secret,
+					IsActive: b.IsActive,
+				}
+				if webhook.Secret == "" {
+					webhook.Secret = generateSecret()
+				}
+				return store.CreateWebhook(ctx, webhook)
+			})
+			continue
+		}
+
+		if current.UserID != b.UserID {
+			item.Action = RestoreActionConflict
+			item.Reason = "webhook belongs to a different user"
+			plan.add(item, nil)
+			continue
+		}
+
+		if current.URL == b.URL && reflect.DeepEqual(current.Events, b.Events) &&
+			current.IsActive == b.IsActive && (b.Secret == "" || current.Secret == b.Secret) {
+			item.Action = RestoreActionUnchanged
+			plan.add(item, nil)
+			continue
+		}
+
+		item.Action = RestoreActionUpdate
+		plan.add(item, func(store storage.Store) error {
+			current, err := store.GetWebhook(ctx, b.ID)
+			if err != nil {
+				return err
+			}
+			webhook := *current
+			webhook.URL = b.URL
+			webhook.Events = append([]string(nil), b.Events...)
+			webhook.IsActive = b.IsActive
+			if b.Secret != "" {
+				webhook.Secret = b.Secret
+			}
+			return store.UpdateWebhook(ctx, &webhook)
+		})
+	}
+	return nil
+}
+
+// validateSettingsBackup 校验备份文档结构
+func validateSettingsBackup(backup *SettingsBackup) error {
+	if backup == nil {
+		return ErrInvalidBackup
+	}
+	if backup.Schema != SettingsBackupSchema {
+		return fmt.Errorf("%w: %q", ErrBackupSchemaMismatch, backup.Schema)
+	}
+	if backup.Version != SettingsBackupVersion {
+		return fmt.Errorf("%w: %d (supported: %d)", ErrBackupVersionMismatch, backup.Version, SettingsBackupVersion)
+	}
+
+	seen := make(map[string]bool, len(backup.SystemDomains))
+	defaults := 0
+	for _, d := range backup.SystemDomains {
+		name := strings.ToLower(strings.TrimSpace(d.Domain))
+		if name == "" {
+			return fmt.Errorf("%w: system domain without name", ErrInvalidBackup)
+		}
+		if seen[name] {
+			return fmt.Errorf("%w: duplicate system domain %s", ErrInvalidBackup, name)
+		}
+		seen[name] = true
+		if d.IsDefault {
+			defaults++
+		}
+	}
+	if defaults > 1 {
+		return fmt.Errorf("%w: more than one default system domain", ErrInvalidBackup)
+	}
+
+	seen = make(map[string]bool, len(backup.Webhooks))
+	for _, w := range backup.Webhooks {
+		if w.ID == "" || w.UserID == "" || w.URL == "" {
+			return fmt.Errorf("%w: webhook requires id, userId and url", ErrInvalidBackup)
+		}
+		if seen[w.ID] {
+			return fmt.Errorf("%w: duplicate webhook %s", ErrInvalidBackup, w.ID)
+		}
+		seen[w.ID] = true
+	}
+	return nil
+}
+
+// listAllWebhooks 列出所有用户的 Webhook
+func (s *SettingsBackupService) listAllWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	const pageSize = 100
+	var webhooks []domain.Webhook
+	for page := 1; ; page++ {
+		users, total, err := s.store.ListUsers(ctx, page, pageSize, "", nil, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			items, err := s.store.ListWebhooks(ctx, user.ID)
+			if err != nil {
+				return nil, err
+			}
+			webhooks = append(webhooks, items...)
+		}
+		if len(users) == 0 || page*pageSize >= total {
+			break
+		}
+	}
+	return webhooks, nil
+}
+
+// updateSystemDomain 读取、修改并保存系统域名
+func updateSystemDomain(ctx context.Context, store storage.Store, id string, mutate func(d *domain.SystemDomain)) error {
+	current, err := store.GetSystemDomain(ctx, id)
+	if err != nil {
+		return err
+	}
+	sysDomain := *current
+	mutate(&sysDomain)
+	if updater, ok := store.(systemDomainUpdater); ok {
+		return updater.UpdateSystemDomain(ctx, &sysDomain)
+	}
+	return store.SaveSystemDomain(ctx, &sysDomain)
+}
+
+func backupSystemDomain(d *domain.SystemDomain) BackupSystemDomain {
+	return BackupSystemDomain{
+		Domain:       strings.ToLower(d.Domain),
+		Status:       d.Status,
+		VerifyToken:  d.VerifyToken,
+		VerifyMethod: d.VerifyMethod,
+		VerifiedAt:   d.VerifiedAt,
+		IsActive:     d.IsActive,
+		IsDefault:    d.IsDefault,
+		MXRecords:    append([]string(nil), d.MXRecords...),
+		Notes:        d.Notes,
+		CreatedAt:    d.CreatedAt,
+	}
+}
+
+func applyBackupSystemDomain(d *domain.SystemDomain, b BackupSystemDomain) {
+	d.Domain = b.Domain
+	d.Status = b.Status
+	d.VerifyToken = b.VerifyToken
+	d.VerifyMethod = b.VerifyMethod
+	d.VerifiedAt = b.VerifiedAt
+	d.IsActive = b.IsActive
+	d.IsDefault = b.IsDefault
+	d.MXRecords = append([]string(nil), b.MXRecords...)
+	d.Notes = b.Notes
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = b.CreatedAt
+	}
+}
+
+// sameBackupSystemDomain 比较域名设置（忽略创建时间，恢复不会修改已有域名的创建时间）
+func sameBackupSystemDomain(a, b BackupSystemDomain) bool {
+	sameVerifiedAt := (a.VerifiedAt == nil) == (b.VerifiedAt == nil) &&
+		(a.VerifiedAt == nil || a.VerifiedAt.Equal(*b.VerifiedAt))
+	return sameVerifiedAt &&
+		a.Domain == b.Domain &&
+		a.Status == b.Status &&
+		a.VerifyToken == b.VerifyToken &&
+		a.VerifyMethod == b.VerifyMethod &&
+		a.IsActive == b.IsActive &&
+		a.IsDefault == b.IsDefault &&
+		a.Notes == b.Notes &&
+		strings.Join(a.MXRecords, ",") == strings.Join(b.MXRecords, ",")
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// seedSettingsStore 构造带有配置、系统域名和 Webhook 的实例
+func seedSettingsStore(t *testing.T) *memory.Store {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+
+	config := domain.DefaultSystemConfig()
+	config.SMTP.Domain = "mx.example.com"
+	config.Mailbox.AllowedDomains = []string{"alpha.example", "beta.example"}
+	config.RateLimit.RequestsPerMinute = 120
+	require.NoError(t, store.SaveSystemConfig(t.Context(), config))
+
+	verifiedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, store.SaveSystemDomain(t.Context(), &domain.SystemDomain{
+		ID: "sd-1", Domain: "alpha.example", Status: domain.SystemDomainStatusVerified,
+		VerifyToken: "token-alpha", VerifyMethod: "dns_txt", VerifiedAt: &verifiedAt,
+		IsActive: true, IsDefault: true, MXRecords: []string{"mx.example.com"},
+		CreatedAt: verifiedAt.Add(-time.Hour), MailboxCount: 42,
+	}))
+	require.NoError(t, store.SaveSystemDomain(t.Context(), &domain.SystemDomain{
+		ID: "sd-2", Domain: "beta.example", Status: domain.SystemDomainStatusPending,
+		VerifyToken: "token-beta", VerifyMethod: "dns_txt", CreatedAt: verifiedAt,
+	}))
+
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: "user-1", Email: "ops@example.com", Role: domain.RoleAdmin}))
+	require.NoError(t, store.CreateWebhook(t.Context(), &domain.Webhook{
+		ID: "wh-1", UserID: "user-1", URL: "https://hooks.example.com/mail",
+		Events: []string{"mail.received"}, Secret: "s3cr3t", IsActive: true,
+	}))
+	return store
+}
+
+// roundTrip 模拟导出文档经 JSON 传输
+func roundTrip(t *testing.T, backup *SettingsBackup) *SettingsBackup {
+	t.Helper()
+	data, err := json.Marshal(backup)
+	require.NoError(t, err)
+	var decoded SettingsBackup
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	return &decoded
+}
+
+func TestSettingsBackup_Export(t *testing.T) {
+	svc := NewSettingsBackupService(seedSettingsStore(t))
+
+	t.Run("默认不导出密钥", func(t *testing.T) {
+		backup, err := svc.Export(t.Context(), false)
+		require.NoError(t, err)
+		assert.Equal(t, SettingsBackupSchema, backup.Schema)
+		assert.Equal(t, SettingsBackupVersion, backup.Version)
+		assert.False(t, backup.IncludesSecrets)
+		require.Len(t, backup.Webhooks, 1)
+		assert.Empty(t, backup.Webhooks[0].Secret)
+
+		data, err := json.Marshal(backup)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "s3cr3t")
+		assert.NotContains(t, string(data), "ops@example.com")
+	})
+
+	t.Run("显式要求时包含密钥", func(t *testing.T) {
+		backup, err := svc.Export(t.Context(), true)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cr3t", backup.Webhooks[0].Secret)
+	})
+
+	t.Run("域名包含验证状态与令牌", func(t *testing.T) {
+		backup, err := svc.Export(t.Context(), false)
+		require.NoError(t, err)
+		require.Len(t, backup.SystemDomains, 2)
+		assert.Equal(t, "alpha.example", backup.SystemDomains[0].Domain)
+		assert.Equal(t, domain.SystemDomainStatusVerified, backup.SystemDomains[0].Status)
+		assert.Equal(t, "token-alpha", backup.SystemDomains[0].VerifyToken)
+	})
+}
+
+func TestSettingsBackup_RoundTrip(t *testing.T) {
+	source := NewSettingsBackupService(seedSettingsStore(t))
+	exported, err := source.Export(t.Context(), true)
+	require.NoError(t, err)
+
+	// 全新实例：只有 Webhook 所属用户（用户数据不在备份范围内）
+	target := memory.NewStore(24 * time.Hour)
+	require.NoError(t, target.CreateUser(t.Context(), &domain.User{ID: "user-1", Email: "ops@example.com"}))
+	svc := NewSettingsBackupService(target)
+
+	var audited []RestoreItem
+	svc.SetAuditFunc(func(actorID string, item RestoreItem) {
+		assert.Equal(t, "admin-1", actorID)
+		audited = append(audited, item)
+	})
+
+	report, err := svc.Restore(t.Context(), roundTrip(t, exported), false, "admin-1")
+	require.NoError(t, err)
+	assert.True(t, report.Applied)
+	assert.Equal(t, 4, report.Created+report.Updated) // 配置 + 2 个域名 + 1 个 Webhook
+	assert.Len(t, audited, 4)
+
+	restored, err := svc.Export(t.Context(), true)
+	require.NoError(t, err)
+	restored.ExportedAt = exported.ExportedAt
+	assert.Equal(t, exported, restored)
+
+	t.Run("重复恢复是幂等的", func(t *testing.T) {
+		audited = nil
+		report, err := svc.Restore(t.Context(), roundTrip(t, exported), false, "admin-1")
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.Created)
+		assert.Equal(t, 0, report.Updated)
+		assert.Equal(t, 4, report.Unchanged)
+		assert.Empty(t, audited)
+	})
+}
+
+func TestSettingsBackup_DryRunConflicts(t *testing.T) {
+	source := NewSettingsBackupService(seedSettingsStore(t))
+	exported, err := source.Export(t.Context(), false)
+	require.NoError(t, err)
+
+	// 目标实例：alpha 已存在但设置不同，beta 已被用户域名占用，Webhook 所属用户不存在
+	target := memory.NewStore(24 * time.Hour)
+	require.NoError(t, target.SaveSystemDomain(t.Context(), &domain.SystemDomain{
+		ID: "other-1", Domain: "alpha.example", Status: domain.SystemDomainStatusPending, VerifyToken: "stale",
+	}))
+	require.NoError(t, target.SaveUserDomain(t.Context(), &domain.UserDomain{ID: "ud-1", UserID: "someone", Domain: "beta.example"}))
+	svc := NewSettingsBackupService(target)
+
+	report, err := svc.Restore(t.Context(), roundTrip(t, exported), true, "admin-1")
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.False(t, report.Applied)
+
+	actions := make(map[string]RestoreAction)
+	for _, item := range report.Items {
+		actions[item.Kind+":"+item.Key] = item.Action
+	}
+	assert.Equal(t, RestoreActionUpdate, actions["system_domain:alpha.example"])
+	assert.Equal(t, RestoreActionConflict, actions["system_domain:beta.example"])
+	assert.Equal(t, RestoreActionConflict, actions["webhook:wh-1"])
+	assert.Equal(t, 2, report.Conflicts)
+
+	// 预演不写入
+	alpha, err := target.GetSystemDomainByDomain(t.Context(), "alpha.example")
+	require.NoError(t, err)
+	assert.Equal(t, "stale", alpha.VerifyToken)
+
+	t.Run("存在冲突时拒绝写入", func(t *testing.T) {
+		report, err := svc.Restore(t.Context(), roundTrip(t, exported), false, "admin-1")
+		assert.ErrorIs(t, err, ErrRestoreConflicts)
+		require.NotNil(t, report)
+		assert.False(t, report.Applied)
+
+		alpha, err := target.GetSystemDomainByDomain(t.Context(), "alpha.example")
+		require.NoError(t, err)
+		assert.Equal(t, "stale", alpha.VerifyToken)
+	})
+}
+
+func TestSettingsBackup_VersionMismatch(t *testing.T) {
+	svc := NewSettingsBackupService(memory.NewStore(24 * time.Hour))
+
+	_, err := svc.Restore(t.Context(), &SettingsBackup{Schema: SettingsBackupSchema, Version: SettingsBackupVersion + 1}, true, "admin-1")
+	assert.ErrorIs(t, err, ErrBackupVersionMismatch)
+
+	_, err = svc.Restore(t.Context(), &SettingsBackup{Schema: "something-else", Version: SettingsBackupVersion}, true, "admin-1")
+	assert.ErrorIs(t, err, ErrBackupSchemaMismatch)
+
+	_, err = svc.Restore(t.Context(), &SettingsBackup{
+		Schema:  SettingsBackupSchema,
+		Version: SettingsBackupVersion,
+		SystemDomains: []BackupSystemDomain{
+			{Domain: "a.example", IsDefault: true},
+			{Domain: "b.example", IsDefault: true},
+		},
+	}, true, "admin-1")
+	assert.ErrorIs(t, err, ErrInvalidBackup)
+}
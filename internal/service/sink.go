@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// MaxSinkSampleRate 抽样间隔上限
+const MaxSinkSampleRate = 1000000
+
+var (
+	ErrSinkRequiresExclusive  = errors.New("sink mode requires a verified exclusive domain")
+	ErrInvalidSinkSampleRate  = errors.New("invalid sink sample rate")
+	ErrSinkDiagnosticsMailbox = errors.New("diagnostics mailbox not found")
+)
+
+// SinkService 域名黑洞模式
+//
+// 开启后 SMTP 接收域名下的任意收件人但不保存邮件，只累计汇总计数；
+// 设置保存在域名记录上，收件时随域名解析一起读取，关闭后立即恢复正常收件。
+type SinkService struct {
+	store domain.Store
+	authz *Authorizer
+	now   func() time.Time
+}
+
+// NewSinkService 创建黑洞模式服务
+func NewSinkService(store domain.Store) *SinkService {
+	return &SinkService{
+		store: store,
+		authz: NewAuthorizer(store),
+		now:   time.Now,
+	}
+}
+
+// ConfigureSystemDomain 设置系统域名的黑洞模式
+func (s *SinkService) ConfigureSystemDomain(ctx context.Context, domainID string, settings domain.SinkSettings) (*domain.SystemDomain, error) {
+	sysDomain, err := s.store.GetSystemDomain(ctx, domainID)
+	if err != nil {
+		return nil, ErrSystemDomainNotFound
+	}
+	if err := s.validate(ctx, settings, nil); err != nil {
+		return nil, err
+	}
+
+	sysDomain.Sink = settings
+	if err := s.store.SaveSystemDomain(ctx, sysDomain); err != nil {
+		return nil, err
+	}
+	return sysDomain, nil
+}
+
+// ConfigureUserDomain 设置用户域名的黑洞模式（仅已验证的独享域名，诊断邮箱须归用户所有）
+func (s *SinkService) ConfigureUserDomain(ctx context.Context, domainID, userID string, settings domain.SinkSettings) (*domain.UserDomain, error) {
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
+	if err != nil {
+		return nil, ErrDomainNotFound
+	}
+	if !s.authz.Can(ctx, userID, userDomain.UserID, userDomain.OrgID, ActionManage) {
+		return nil, ErrNotDomainOwner
+	}
+	if settings.Enabled && (userDomain.Status != domain.DomainStatusVerified || userDomain.Mode != domain.DomainModeExclusive) {
+		return nil, ErrSinkRequiresExclusive
+	}
+	if err := s.validate(ctx, settings, &userID); err != nil {
+		return nil, err
+	}
+
+	userDomain.Sink = settings
+	if err := s.store.SaveUserDomain(ctx, userDomain); err != nil {
+		return nil, err
+	}
+	return userDomain, nil
+}
+
+// validate 校验抽样设置；userID 非空时诊断邮箱必须是该用户可管理的邮箱
+func (s *SinkService) validate(ctx context.Context, settings domain.SinkSettings, userID *string) error {
+	if settings.SampleRate < 0 || settings.SampleRate > MaxSinkSampleRate {
+		return ErrInvalidSinkSampleRate
+	}
+	if settings.SampleRate > 0 && settings.DiagnosticsMailboxID == "" {
+		return ErrSinkDiagnosticsMailbox
+	}
+	if settings.DiagnosticsMailboxID == "" {
+		return nil
+	}
+
+	mailbox, err := s.store.GetMailbox(ctx, settings.DiagnosticsMailboxID)
+	if err != nil {
+		return ErrSinkDiagnosticsMailbox
+	}
+	if userID != nil && !s.authz.CanAccessMailbox(ctx, *userID, mailbox, ActionManage) {
+		return ErrSinkDiagnosticsMailbox
+	}
+	return nil
+}
+
+// Record 累计一封黑洞邮件，返回需要抽样投递的诊断邮箱 ID（不抽样时为空）
+//
+// 抽样按全局累计数取模，多个实例共享计数时抽样率依然准确。
+func (s *SinkService) Record(ctx context.Context, domainName string, settings domain.SinkSettings, sender string, size int64) (string, error) {
+	total, err := s.store.RecordSinkMessage(ctx, domainName, sender, size, s.now().UTC())
+	if err != nil {
+		return "", err
+	}
+	if settings.SampleRate > 0 && settings.DiagnosticsMailboxID != "" && total%int64(settings.SampleRate) == 0 {
+		return settings.DiagnosticsMailboxID, nil
+	}
+	return "", nil
+}
+
+// RecordSample 累计一封已投递到诊断邮箱的抽样邮件
+func (s *SinkService) RecordSample(ctx context.Context, domainName string) error {
+	return s.store.RecordSinkSample(ctx, domainName)
+}
+
+// SystemDomainStats 获取系统域名的黑洞统计
+func (s *SinkService) SystemDomainStats(ctx context.Context, domainID string) (*domain.SinkStats, error) {
+	sysDomain, err := s.store.GetSystemDomain(ctx, domainID)
+	if err != nil {
+		return nil, ErrSystemDomainNotFound
+	}
+	return s.store.GetSinkStats(ctx, sysDomain.Domain)
+}
+
+// UserDomainStats 获取用户域名的黑洞统计
+func (s *SinkService) UserDomainStats(ctx context.Context, domainID, userID string) (*domain.SinkStats, error) {
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
+	if err != nil {
+		return nil, ErrDomainNotFound
+	}
+	if !s.authz.Can(ctx, userID, userDomain.UserID, userDomain.OrgID, ActionRead) {
+		return nil, ErrNotDomainOwner
+	}
+	return s.store.GetSinkStats(ctx, userDomain.Domain)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestSinkService_ConfigureUserDomain(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	owner, stranger := "user-1", "user-2"
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{
+		ID: "ud-1", UserID: owner, Domain: "owned.example", Mode: domain.DomainModeShared,
+		Status: domain.DomainStatusVerified, IsActive: true,
+	}))
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-own", Address: "diag@owned.example", Domain: "owned.example", UserID: &owner, CreatedAt: time.Now(),
+	}))
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-other", Address: "x@other.example", Domain: "other.example", UserID: &stranger, CreatedAt: time.Now(),
+	}))
+	sinks := NewSinkService(store)
+	on := domain.SinkSettings{Enabled: true}
+
+	t.Run("共享模式不能开启", func(t *testing.T) {
+		_, err := sinks.ConfigureUserDomain(t.Context(), "ud-1", owner, on)
+		assert.ErrorIs(t, err, ErrSinkRequiresExclusive)
+	})
+
+	_, err := NewUserDomainService(store, nil).UpdateDomainMode(t.Context(), "ud-1", owner, domain.DomainModeExclusive)
+	require.NoError(t, err)
+
+	t.Run("非所有者不能设置", func(t *testing.T) {
+		_, err := sinks.ConfigureUserDomain(t.Context(), "ud-1", stranger, on)
+		assert.ErrorIs(t, err, ErrNotDomainOwner)
+	})
+
+	t.Run("诊断邮箱必须归所有者", func(t *testing.T) {
+		_, err := sinks.ConfigureUserDomain(t.Context(), "ud-1", owner, domain.SinkSettings{Enabled: true, SampleRate: 10, DiagnosticsMailboxID: "mb-other"})
+		assert.ErrorIs(t, err, ErrSinkDiagnosticsMailbox)
+		_, err = sinks.ConfigureUserDomain(t.Context(), "ud-1", owner, domain.SinkSettings{Enabled: true, SampleRate: 10})
+		assert.ErrorIs(t, err, ErrSinkDiagnosticsMailbox)
+		_, err = sinks.ConfigureUserDomain(t.Context(), "ud-1", owner, domain.SinkSettings{Enabled: true, SampleRate: -1})
+		assert.ErrorIs(t, err, ErrInvalidSinkSampleRate)
+	})
+
+	t.Run("独享模式开启后解析为黑洞，切回共享模式后失效", func(t *testing.T) {
+		_, err := sinks.ConfigureUserDomain(t.Context(), "ud-1", owner, domain.SinkSettings{Enabled: true, SampleRate: 10, DiagnosticsMailboxID: "mb-own"})
+		require.NoError(t, err)
+		settings := ResolveDomain(t.Context(), store, "owned.example").Sink()
+		require.NotNil(t, settings)
+		assert.Equal(t, 10, settings.SampleRate)
+
+		_, err = NewUserDomainService(store, nil).UpdateDomainMode(t.Context(), "ud-1", owner, domain.DomainModeShared)
+		require.NoError(t, err)
+		assert.Nil(t, ResolveDomain(t.Context(), store, "owned.example").Sink())
+	})
+
+	t.Run("域名收件统计附带黑洞计数", func(t *testing.T) {
+		_, err := sinks.Record(t.Context(), "owned.example", domain.SinkSettings{}, "a@example.net", 120)
+		require.NoError(t, err)
+
+		stats, err := NewStatsService(store).DomainStats(t.Context(), owner, "ud-1", DefaultStatsWindow)
+		require.NoError(t, err)
+		require.NotNil(t, stats.Sink)
+		assert.EqualValues(t, 1, stats.Sink.Messages)
+		assert.EqualValues(t, 120, stats.Sink.Bytes)
+	})
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+const (
+	// DefaultStatsWindow 默认统计窗口
+	DefaultStatsWindow = 7 * 24 * time.Hour
+	// MaxStatsWindow 统计窗口上限
+	MaxStatsWindow = 90 * 24 * time.Hour
+	// statsTopSenders 发件人排行数量
+	statsTopSenders = 10
+)
+
+var (
+	ErrInvalidStatsWindow  = errors.New("invalid stats window")
+	ErrStatsWindowTooLarge = errors.New("stats window exceeds 90 days")
+)
+
+// StatsService 邮箱与域名收件统计服务
+type StatsService struct {
+	store domain.Store
+	now   func() time.Time
+}
+
+// NewStatsService 创建统计服务
+func NewStatsService(store domain.Store) *StatsService {
+	return &StatsService{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// ParseStatsWindow 解析统计窗口，支持 "7d" 形式的天数或 Go 时长（如 "36h"），空值取默认 7 天
+func ParseStatsWindow(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return DefaultStatsWindow, nil
+	}
+
+	var window time.Duration
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, ErrInvalidStatsWindow
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return 0, ErrInvalidStatsWindow
+		}
+		window = d
+	}
+
+	if window < time.Hour {
+		return 0, ErrInvalidStatsWindow
+	}
+	if window > MaxStatsWindow {
+		return 0, ErrStatsWindowTooLarge
+	}
+	return window, nil
+}
+
+// MailboxStats 获取单个邮箱的收件统计
+func (s *StatsService) MailboxStats(ctx context.Context, mailboxID string, window time.Duration) (*domain.MessageStats, error) {
+	return s.collect(ctx, []string{mailboxID}, window)
+}
+
+// DomainStats 获取用户域名下所有邮箱的收件统计，附带按本地部分的分布
+func (s *StatsService) DomainStats(ctx context.Context, userID, domainID string, window time.Duration) (*domain.MessageStats, error) {
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
+	if err != nil {
+		return nil, ErrDomainNotFound
+	}
+	if !NewAuthorizer(s.store).Can(ctx, userID, userDomain.UserID, userDomain.OrgID, ActionRead) {
+		return nil, ErrNotDomainOwner
+	}
+
+	localParts := make(map[string]string)
+	var mailboxIDs []string
+	for _, mb := range s.store.ListMailboxes(ctx) {
+		if !strings.EqualFold(mb.Domain, userDomain.Domain) {
+			continue
+		}
+		mailboxIDs = append(mailboxIDs, mb.ID)
+		localParts[mb.ID] = mb.LocalPart
+	}
+
+	stats, err := s.collect(ctx, mailboxIDs, window)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.LocalParts = make([]domain.LocalPartCount, 0, len(stats.ByMailbox))
+	for mailboxID, count := range stats.ByMailbox {
+		stats.LocalParts = append(stats.LocalParts, domain.LocalPartCount{LocalPart: localParts[mailboxID], Count: count})
+	}
+	sort.Slice(stats.LocalParts, func(i, j int) bool {
+		if stats.LocalParts[i].Count != stats.LocalParts[j].Count {
+			return stats.LocalParts[i].Count > stats.LocalParts[j].Count
+		}
+		return stats.LocalParts[i].LocalPart < stats.LocalParts[j].LocalPart
+	})
+
+	// 黑洞模式下邮件不入库，附带汇总计数
+	sink, err := s.store.GetSinkStats(ctx, userDomain.Domain)
+	if err != nil {
+		return nil, err
+	}
+	if userDomain.Sink.Enabled || sink.Messages > 0 {
+		stats.Sink = sink
+	}
+	return stats, nil
+}
+
+// collect 查询统计并补齐空的小时桶
+//
+// 窗口按 UTC 整点对齐：结束于当前小时之后的整点，保证每个桶都是完整的一小时，
+// 与本地时区和夏令时切换无关。
+func (s *StatsService) collect(ctx context.Context, mailboxIDs []string, window time.Duration) (*domain.MessageStats, error) {
+	until := s.now().UTC().Truncate(time.Hour).Add(time.Hour)
+	since := until.Add(-window.Truncate(time.Hour))
+
+	stats, err := s.store.GetMessageStats(ctx, domain.MessageStatsQuery{
+		MailboxIDs: mailboxIDs,
+		Since:      since,
+		Until:      until,
+		TopSenders: statsTopSenders,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[time.Time]int, len(stats.Hourly))
+	for _, bucket := range stats.Hourly {
+		counts[bucket.Hour.UTC()] = bucket.Count
+	}
+	hourly := make([]domain.HourlyCount, 0, int(until.Sub(since)/time.Hour))
+	for hour := since; hour.Before(until); hour = hour.Add(time.Hour) {
+		hourly = append(hourly, domain.HourlyCount{Hour: hour, Count: counts[hour]})
+	}
+
+	stats.Since = since
+	stats.Until = until
+	stats.Hourly = hourly
+	return stats, nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// seedStatsStore 构造两个域名下的邮箱，邮件接收时间跨越纽约夏令时切换（2026-03-08 02:00 EST -> 03:00 EDT）
+func seedStatsStore(t *testing.T) *memory.Store {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+
+	for _, mb := range []domain.Mailbox{
+		{ID: "mb-sales", Address: "sales@corp.example", LocalPart: "sales", Domain: "corp.example", Token: "tok-sales"},
+		{ID: "mb-ops", Address: "ops@corp.example", LocalPart: "ops", Domain: "corp.example", Token: "tok-ops"},
+		{ID: "mb-other", Address: "x@other.example", LocalPart: "x", Domain: "other.example", Token: "tok-other"},
+	} {
+		mailbox := mb
+		mailbox.CreatedAt = time.Now()
+		require.NoError(t, store.SaveMailbox(t.Context(), &mailbox))
+	}
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{ID: "ud-corp", UserID: "user-1", Domain: "corp.example"}))
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{ID: "ud-other", UserID: "user-2", Domain: "other.example"}))
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	local := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 8, hour, minute, 0, 0, newYork)
+	}
+
+	messages := []struct {
+		id, mailbox, from string
+		received          time.Time
+		size              int64
+		read              bool
+	}{
+		{"m1", "mb-sales", "Alice@Example.com", local(1, 10), 100, true},  // 06:10 UTC
+		{"m2", "mb-sales", "alice@example.com", local(1, 50), 300, false}, // 06:50 UTC
+		{"m3", "mb-sales", "bob@example.com", local(3, 5), 200, false},    // 07:05 UTC（本地跳过 02 点）
+		{"m4", "mb-ops", "alice@example.com", local(4, 30), 400, false},   // 08:30 UTC
+		{"m5", "mb-other", "eve@example.com", local(3, 30), 1000, false},  // 其他域名
+		{"m6", "mb-sales", "old@example.com", local(1, 0).AddDate(0, 0, -30), 50, false},
+	}
+	for _, m := range messages {
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+			ID: m.id, MailboxID: m.mailbox, From: m.from, Subject: m.id,
+			ReceivedAt: m.received, CreatedAt: m.received, Size: m.size, IsRead: m.read,
+		}))
+	}
+	return store
+}
+
+func newTestStatsService(store domain.Store) *StatsService {
+	svc := NewStatsService(store)
+	svc.now = func() time.Time { return time.Date(2026, 3, 8, 12, 15, 0, 0, time.UTC) }
+	return svc
+}
+
+func TestParseStatsWindow(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":    DefaultStatsWindow,
+		"7d":  7 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+		"90d": MaxStatsWindow,
+	}
+	for raw, want := range cases {
+		got, err := ParseStatsWindow(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, got, raw)
+	}
+
+	_, err := ParseStatsWindow("91d")
+	assert.ErrorIs(t, err, ErrStatsWindowTooLarge)
+	_, err = ParseStatsWindow("abc")
+	assert.ErrorIs(t, err, ErrInvalidStatsWindow)
+	_, err = ParseStatsWindow("10m")
+	assert.ErrorIs(t, err, ErrInvalidStatsWindow)
+}
+
+func TestStatsService_MailboxStats(t *testing.T) {
+	svc := newTestStatsService(seedStatsStore(t))
+
+	stats, err := svc.MailboxStats(t.Context(), "mb-sales", 24*time.Hour)
+	require.NoError(t, err)
+
+	t.Run("窗口按 UTC 整点对齐", func(t *testing.T) {
+		assert.Equal(t, time.Date(2026, 3, 7, 13, 0, 0, 0, time.UTC), stats.Since)
+		assert.Equal(t, time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC), stats.Until)
+		assert.Len(t, stats.Hourly, 24)
+	})
+
+	t.Run("夏令时切换前后的小时桶连续且计数准确", func(t *testing.T) {
+		counts := make(map[int]int)
+		for i, bucket := range stats.Hourly {
+			assert.Equal(t, time.UTC, bucket.Hour.Location())
+			if i > 0 {
+				assert.Equal(t, time.Hour, bucket.Hour.Sub(stats.Hourly[i-1].Hour))
+			}
+			if bucket.Count > 0 {
+				counts[bucket.Hour.Hour()] = bucket.Count
+			}
+		}
+		assert.Equal(t, map[int]int{6: 2, 7: 1}, counts)
+	})
+
+	t.Run("汇总与发件人排行", func(t *testing.T) {
+		assert.Equal(t, 3, stats.Total)
+		assert.Equal(t, 2, stats.Unread)
+		assert.Equal(t, int64(200), stats.AverageSize)
+		assert.Equal(t, []domain.SenderCount{
+			{Sender: "alice@example.com", Count: 2},
+			{Sender: "bob@example.com", Count: 1},
+		}, stats.TopSenders)
+		assert.Empty(t, stats.LocalParts)
+	})
+}
+
+func TestStatsService_DomainStats(t *testing.T) {
+	svc := newTestStatsService(seedStatsStore(t))
+
+	t.Run("汇总域名下所有邮箱并按本地部分拆分", func(t *testing.T) {
+		stats, err := svc.DomainStats(t.Context(), "user-1", "ud-corp", 24*time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, 4, stats.Total)
+		assert.Equal(t, []domain.LocalPartCount{
+			{LocalPart: "sales", Count: 3},
+			{LocalPart: "ops", Count: 1},
+		}, stats.LocalParts)
+		assert.Equal(t, domain.SenderCount{Sender: "alice@example.com", Count: 3}, stats.TopSenders[0])
+	})
+
+	t.Run("非所有者不能读取域名统计", func(t *testing.T) {
+		_, err := svc.DomainStats(t.Context(), "user-2", "ud-corp", 24*time.Hour)
+		assert.ErrorIs(t, err, ErrNotDomainOwner)
+	})
+
+	t.Run("域名不存在", func(t *testing.T) {
+		_, err := svc.DomainStats(t.Context(), "user-1", "missing", 24*time.Hour)
+		assert.ErrorIs(t, err, ErrDomainNotFound)
+	})
+}
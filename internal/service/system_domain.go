@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
@@ -23,12 +24,27 @@ var (
 	ErrSystemDomainHasMailboxes  = errors.New("cannot delete domain with active mailboxes")
 	ErrInvalidSystemDomain       = errors.New("invalid system domain")
 	ErrCannotDeleteDefaultDomain = errors.New("cannot delete default domain")
+	ErrSystemDomainOwnedByUser   = errors.New("domain is verified by a user")
+	ErrUserDomainNotFound        = errors.New("user domain not found")
+	ErrInvalidClaimStrategy      = errors.New("invalid mailbox strategy")
 )
 
+// ClaimMailboxStrategy 用户域名转为系统域名时已有邮箱的处理策略
+type ClaimMailboxStrategy string
+
+const (
+	ClaimMailboxKeep        ClaimMailboxStrategy = "keep"          // 保留邮箱，不改变过期时间
+	ClaimMailboxExpireIn30d ClaimMailboxStrategy = "expire-in-30d" // 30 天后过期（已更早过期的不变）
+)
+
+// claimMailboxGracePeriod expire-in-30d 策略的宽限期
+const claimMailboxGracePeriod = 30 * 24 * time.Hour
+
 // SystemDomainService 系统域名服务
 type SystemDomainService struct {
-	store domain.Store
-	cfg   *config.Config
+	store   domain.Store
+	cfg     *config.Config
+	webhook *WebhookService // 可选：用于通知原域名所有者
 }
 
 // NewSystemDomainService 创建系统域名服务
@@ -39,6 +55,16 @@ func NewSystemDomainService(store domain.Store, cfg *config.Config) *SystemDomai
 	}
 }
 
+// SetWebhookService 设置 Webhook 服务（用于域名转换通知）
+func (s *SystemDomainService) SetWebhookService(webhook *WebhookService) {
+	s.webhook = webhook
+}
+
+// ResolveDomain 解析域名归属（系统域名或用户域名）
+func (s *SystemDomainService) ResolveDomain(ctx context.Context, domainName string) *DomainResolution {
+	return ResolveDomain(ctx, s.store, domainName)
+}
+
 // GetStore 获取存储接口（用于内部初始化）
 func (s *SystemDomainService) GetStore() domain.Store {
 	return s.store
@@ -61,7 +87,7 @@ type AddSystemDomainInput struct {
 // 返回值:
 //   - *domain.SystemDomain: 创建的系统域名
 //   - error: 错误信息
-func (s *SystemDomainService) AddSystemDomain(input AddSystemDomainInput) (*domain.SystemDomain, error) {
+func (s *SystemDomainService) AddSystemDomain(ctx context.Context, input AddSystemDomainInput) (*domain.SystemDomain, error) {
 	// 验证域名格式
 	domainName := strings.TrimSpace(strings.ToLower(input.Domain))
 	if !isValidSystemDomain(domainName) {
@@ -69,11 +95,16 @@ func (s *SystemDomainService) AddSystemDomain(input AddSystemDomainInput) (*doma
 	}
 
 	// 检查域名是否已存在
-	_, err := s.store.GetSystemDomainByDomain(domainName)
+	_, err := s.store.GetSystemDomainByDomain(ctx, domainName)
 	if err == nil {
 		return nil, ErrSystemDomainAlreadyExists
 	}
 
+	// 已被用户验证的域名需要通过 ClaimFromUser 显式转换
+	if err := s.checkUserOwnership(ctx, domainName); err != nil {
+		return nil, err
+	}
+
 	// 生成验证令牌
 	verifyToken := generateSystemToken(32)
 
@@ -96,7 +127,7 @@ func (s *SystemDomainService) AddSystemDomain(input AddSystemDomainInput) (*doma
 		Notes:        input.Notes,
 	}
 
-	if err := s.store.SaveSystemDomain(sysDomain); err != nil {
+	if err := s.store.SaveSystemDomain(ctx, sysDomain); err != nil {
 		return nil, err
 	}
 
@@ -113,8 +144,8 @@ func (s *SystemDomainService) AddSystemDomain(input AddSystemDomainInput) (*doma
 // 返回值:
 //   - *domain.SystemDomain: 验证后的域名信息
 //   - error: 错误信息
-func (s *SystemDomainService) VerifySystemDomain(domainID string) (*domain.SystemDomain, error) {
-	sysDomain, err := s.store.GetSystemDomain(domainID)
+func (s *SystemDomainService) VerifySystemDomain(ctx context.Context, domainID string) (*domain.SystemDomain, error) {
+	sysDomain, err := s.store.GetSystemDomain(ctx, domainID)
 	if err != nil {
 		return nil, ErrSystemDomainNotFound
 	}
@@ -132,7 +163,7 @@ func (s *SystemDomainService) VerifySystemDomain(domainID string) (*domain.Syste
 		now := time.Now().UTC()
 		sysDomain.Status = domain.SystemDomainStatusFailed
 		sysDomain.LastCheckAt = &now
-		s.store.SaveSystemDomain(sysDomain)
+		s.store.SaveSystemDomain(ctx, sysDomain)
 		return nil, ErrSystemDomainVerifyFailed
 	}
 
@@ -143,7 +174,7 @@ func (s *SystemDomainService) VerifySystemDomain(domainID string) (*domain.Syste
 	sysDomain.LastCheckAt = &now
 	sysDomain.IsActive = true
 
-	if err := s.store.SaveSystemDomain(sysDomain); err != nil {
+	if err := s.store.SaveSystemDomain(ctx, sysDomain); err != nil {
 		return nil, err
 	}
 
@@ -161,17 +192,21 @@ func (s *SystemDomainService) VerifySystemDomain(domainID string) (*domain.Syste
 // 返回值:
 //   - *domain.SystemDomain: 找回的域名信息
 //   - error: 错误信息
-func (s *SystemDomainService) RecoverSystemDomain(domainName string, createdBy string) (*domain.SystemDomain, error) {
+func (s *SystemDomainService) RecoverSystemDomain(ctx context.Context, domainName string, createdBy string) (*domain.SystemDomain, error) {
 	domainName = strings.TrimSpace(strings.ToLower(domainName))
 	if !isValidSystemDomain(domainName) {
 		return nil, ErrInvalidSystemDomain
 	}
 
 	// 检查域名是否已存在
-	existingDomain, err := s.store.GetSystemDomainByDomain(domainName)
+	existingDomain, err := s.store.GetSystemDomainByDomain(ctx, domainName)
 	if err == nil {
 		// 域名已存在，尝试重新验证
-		return s.VerifySystemDomain(existingDomain.ID)
+		return s.VerifySystemDomain(ctx, existingDomain.ID)
+	}
+
+	if err := s.checkUserOwnership(ctx, domainName); err != nil {
+		return nil, err
 	}
 
 	// 域名不存在，尝试查找 DNS TXT 记录中的验证令牌
@@ -215,21 +250,149 @@ func (s *SystemDomainService) RecoverSystemDomain(domainName string, createdBy s
 		Notes:        "通过找回功能恢复",
 	}
 
-	if err := s.store.SaveSystemDomain(sysDomain); err != nil {
+	if err := s.store.SaveSystemDomain(ctx, sysDomain); err != nil {
 		return nil, err
 	}
 
 	return sysDomain, nil
 }
 
+// ClaimFromUserInput 用户域名转换输入
+type ClaimFromUserInput struct {
+	Domain          string
+	ClaimedBy       string // 操作的超级管理员ID
+	MailboxStrategy ClaimMailboxStrategy
+	Notes           string
+}
+
+// ClaimFromUserResult 用户域名转换结果
+type ClaimFromUserResult struct {
+	SystemDomain      *domain.SystemDomain `json:"systemDomain"`
+	PreviousOwnerID   string               `json:"previousOwnerId"`
+	MailboxStrategy   ClaimMailboxStrategy `json:"mailboxStrategy"`
+	AffectedMailboxes int                  `json:"affectedMailboxes"`
+}
+
+// ClaimFromUser 将用户域名转换为系统域名
+//
+// 创建已验证的系统域名记录并删除原用户域名，按策略处理该域名下已有邮箱，
+// 最后通过 Webhook 通知原所有者。操作人和时间记录在系统域名备注中。
+//
+// 参数:
+//   - input: 转换输入
+//
+// 返回值:
+//   - *ClaimFromUserResult: 转换结果
+//   - error: 错误信息
+func (s *SystemDomainService) ClaimFromUser(ctx context.Context, input ClaimFromUserInput) (*ClaimFromUserResult, error) {
+	domainName := strings.TrimSpace(strings.ToLower(input.Domain))
+	if !isValidSystemDomain(domainName) {
+		return nil, ErrInvalidSystemDomain
+	}
+
+	strategy := input.MailboxStrategy
+	if strategy == "" {
+		strategy = ClaimMailboxKeep
+	}
+	if strategy != ClaimMailboxKeep && strategy != ClaimMailboxExpireIn30d {
+		return nil, ErrInvalidClaimStrategy
+	}
+
+	if _, err := s.store.GetSystemDomainByDomain(ctx, domainName); err == nil {
+		return nil, ErrSystemDomainAlreadyExists
+	}
+
+	userDomain, err := s.store.GetUserDomainByDomain(ctx, domainName)
+	if err != nil || userDomain == nil {
+		return nil, ErrUserDomainNotFound
+	}
+
+	now := time.Now().UTC()
+	notes := fmt.Sprintf("由用户域名转换：原所有者 %s，操作者 %s，时间 %s，邮箱策略 %s",
+		userDomain.UserID, input.ClaimedBy, now.Format(time.RFC3339), strategy)
+	if input.Notes != "" {
+		notes = input.Notes + "\n" + notes
+	}
+
+	sysDomain := &domain.SystemDomain{
+		ID:           uuid.NewString(),
+		Domain:       domainName,
+		Status:       domain.SystemDomainStatusVerified,
+		VerifyToken:  userDomain.VerifyToken,
+		VerifyMethod: "dns_txt",
+		CreatedAt:    now,
+		VerifiedAt:   &now,
+		LastCheckAt:  &now,
+		CreatedBy:    input.ClaimedBy,
+		MXRecords:    s.generateSystemMXRecords(domainName),
+		IsActive:     true,
+		IsDefault:    false,
+		MailboxCount: userDomain.MailboxCount,
+		Notes:        notes,
+	}
+
+	// 先写系统域名再删用户域名：中途失败时解析仍以系统域名为准，邮件不会丢失
+	if err := s.store.SaveSystemDomain(ctx, sysDomain); err != nil {
+		return nil, err
+	}
+	if err := s.store.DeleteUserDomain(ctx, userDomain.ID); err != nil {
+		return nil, err
+	}
+
+	affected := 0
+	for _, mb := range s.store.ListMailboxes(ctx) {
+		if !strings.EqualFold(mb.Domain, domainName) {
+			continue
+		}
+		affected++
+		if strategy != ClaimMailboxExpireIn30d {
+			continue
+		}
+		deadline := now.Add(claimMailboxGracePeriod)
+		if mb.ExpiresAt != nil && mb.ExpiresAt.Before(deadline) {
+			continue
+		}
+		mailbox := mb
+		mailbox.ExpiresAt = &deadline
+		if err := s.store.SaveMailbox(ctx, &mailbox); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.webhook != nil {
+		_ = s.webhook.TriggerEvent(ctx, userDomain.UserID, domain.WebhookEventDomainClaimed, map[string]interface{}{
+			"domain":            domainName,
+			"mailboxStrategy":   strategy,
+			"affectedMailboxes": affected,
+			"claimedAt":         now,
+		})
+	}
+
+	return &ClaimFromUserResult{
+		SystemDomain:      sysDomain,
+		PreviousOwnerID:   userDomain.UserID,
+		MailboxStrategy:   strategy,
+		AffectedMailboxes: affected,
+	}, nil
+}
+
+// checkUserOwnership 检查域名是否已被用户验证占用
+func (s *SystemDomainService) checkUserOwnership(ctx context.Context, domainName string) error {
+	userDomain, err := s.store.GetUserDomainByDomain(ctx, domainName)
+	if err == nil && userDomain != nil && userDomain.Status == domain.DomainStatusVerified {
+		return ErrSystemDomainOwnedByUser
+	}
+	return nil
+}
+
 // ListSystemDomains 列出所有系统域名
-func (s *SystemDomainService) ListSystemDomains() ([]*domain.SystemDomain, error) {
-	return s.store.ListSystemDomains()
+func (s *SystemDomainService) ListSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
+	return s.store.ListSystemDomains(ctx)
 }
 
 // GetSystemDomain 获取系统域名详情
-func (s *SystemDomainService) GetSystemDomain(domainID string) (*domain.SystemDomain, error) {
-	sysDomain, err := s.store.GetSystemDomain(domainID)
+func (s *SystemDomainService) GetSystemDomain(ctx context.Context, domainID string) (*domain.SystemDomain, error) {
+	sysDomain, err := s.store.GetSystemDomain(ctx, domainID)
 	if err != nil {
 		return nil, ErrSystemDomainNotFound
 	}
@@ -243,8 +406,8 @@ func (s *SystemDomainService) GetSystemDomain(domainID string) (*domain.SystemDo
 //
 // 返回值:
 //   - error: 错误信息
-func (s *SystemDomainService) DeleteSystemDomain(domainID string) error {
-	sysDomain, err := s.store.GetSystemDomain(domainID)
+func (s *SystemDomainService) DeleteSystemDomain(ctx context.Context, domainID string) error {
+	sysDomain, err := s.store.GetSystemDomain(ctx, domainID)
 	if err != nil {
 		return ErrSystemDomainNotFound
 	}
@@ -259,7 +422,7 @@ func (s *SystemDomainService) DeleteSystemDomain(domainID string) error {
 		return ErrSystemDomainHasMailboxes
 	}
 
-	return s.store.DeleteSystemDomain(domainID)
+	return s.store.DeleteSystemDomain(ctx, domainID)
 }
 
 // ToggleDomainStatus 切换域名状态（启用/禁用）
@@ -271,8 +434,8 @@ func (s *SystemDomainService) DeleteSystemDomain(domainID string) error {
 // 返回值:
 //   - *domain.SystemDomain: 更新后的域名信息
 //   - error: 错误信息
-func (s *SystemDomainService) ToggleDomainStatus(domainID string, isActive bool) (*domain.SystemDomain, error) {
-	sysDomain, err := s.store.GetSystemDomain(domainID)
+func (s *SystemDomainService) ToggleDomainStatus(ctx context.Context, domainID string, isActive bool) (*domain.SystemDomain, error) {
+	sysDomain, err := s.store.GetSystemDomain(ctx, domainID)
 	if err != nil {
 		return nil, ErrSystemDomainNotFound
 	}
@@ -284,13 +447,29 @@ func (s *SystemDomainService) ToggleDomainStatus(domainID string, isActive bool)
 
 	sysDomain.IsActive = isActive
 
-	if err := s.store.SaveSystemDomain(sysDomain); err != nil {
+	if err := s.store.SaveSystemDomain(ctx, sysDomain); err != nil {
 		return nil, err
 	}
 
 	return sysDomain, nil
 }
 
+// SetAllowPublicInboxes 设置域名是否允许创建时直接设为公开收件箱
+//
+// 只影响之后的创建请求，已有的公开收件箱保持不变（由管理员单独取消）。
+func (s *SystemDomainService) SetAllowPublicInboxes(ctx context.Context, domainID string, allow bool) (*domain.SystemDomain, error) {
+	sysDomain, err := s.store.GetSystemDomain(ctx, domainID)
+	if err != nil {
+		return nil, ErrSystemDomainNotFound
+	}
+
+	sysDomain.AllowPublicInboxes = allow
+	if err := s.store.SaveSystemDomain(ctx, sysDomain); err != nil {
+		return nil, err
+	}
+	return sysDomain, nil
+}
+
 // SetDefaultDomain 设置默认域名
 //
 // 参数:
@@ -298,8 +477,8 @@ func (s *SystemDomainService) ToggleDomainStatus(domainID string, isActive bool)
 //
 // 返回值:
 //   - error: 错误信息
-func (s *SystemDomainService) SetDefaultDomain(domainID string) error {
-	sysDomain, err := s.store.GetSystemDomain(domainID)
+func (s *SystemDomainService) SetDefaultDomain(ctx context.Context, domainID string) error {
+	sysDomain, err := s.store.GetSystemDomain(ctx, domainID)
 	if err != nil {
 		return ErrSystemDomainNotFound
 	}
@@ -310,7 +489,7 @@ func (s *SystemDomainService) SetDefaultDomain(domainID string) error {
 	}
 
 	// 取消其他域名的默认状态
-	allDomains, err := s.store.ListSystemDomains()
+	allDomains, err := s.store.ListSystemDomains(ctx)
 	if err != nil {
 		return err
 	}
@@ -318,13 +497,13 @@ func (s *SystemDomainService) SetDefaultDomain(domainID string) error {
 	for _, d := range allDomains {
 		if d.ID != domainID && d.IsDefault {
 			d.IsDefault = false
-			s.store.SaveSystemDomain(d)
+			s.store.SaveSystemDomain(ctx, d)
 		}
 	}
 
 	// 设置当前域名为默认
 	sysDomain.IsDefault = true
-	return s.store.SaveSystemDomain(sysDomain)
+	return s.store.SaveSystemDomain(ctx, sysDomain)
 }
 
 // GetSetupInstructions 获取域名配置说明
@@ -335,8 +514,8 @@ func (s *SystemDomainService) SetDefaultDomain(domainID string) error {
 // 返回值:
 //   - map[string]interface{}: 配置说明
 //   - error: 错误信息
-func (s *SystemDomainService) GetSetupInstructions(domainID string) (map[string]interface{}, error) {
-	sysDomain, err := s.store.GetSystemDomain(domainID)
+func (s *SystemDomainService) GetSetupInstructions(ctx context.Context, domainID string) (map[string]interface{}, error) {
+	sysDomain, err := s.store.GetSystemDomain(ctx, domainID)
 	if err != nil {
 		return nil, ErrSystemDomainNotFound
 	}
@@ -381,10 +560,10 @@ func (s *SystemDomainService) GetSetupInstructions(domainID string) (map[string]
 // 返回值:
 //   - int: 删除的域名数量
 //   - error: 错误信息
-func (s *SystemDomainService) CleanupUnverifiedDomains() (int, error) {
+func (s *SystemDomainService) CleanupUnverifiedDomains(ctx context.Context) (int, error) {
 	// 删除 24 小时前创建且未验证的域名
 	before := time.Now().UTC().Add(-24 * time.Hour)
-	return s.store.DeleteUnverifiedSystemDomains(before)
+	return s.store.DeleteUnverifiedSystemDomains(ctx, before)
 }
 
 // GetActiveDomains 获取所有激活的系统域名
@@ -392,8 +571,8 @@ func (s *SystemDomainService) CleanupUnverifiedDomains() (int, error) {
 // 返回值:
 //   - []*domain.SystemDomain: 激活的系统域名列表
 //   - error: 错误信息
-func (s *SystemDomainService) GetActiveDomains() ([]*domain.SystemDomain, error) {
-	return s.store.ListActiveSystemDomains()
+func (s *SystemDomainService) GetActiveDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
+	return s.store.ListActiveSystemDomains(ctx)
 }
 
 // GetAllActiveDomains 获取所有激活的域名（包括系统域名和用户域名）
@@ -403,8 +582,8 @@ func (s *SystemDomainService) GetActiveDomains() ([]*domain.SystemDomain, error)
 // 返回值:
 //   - []string: 激活的域名列表
 //   - error: 错误信息
-func (s *SystemDomainService) GetAllActiveDomains() ([]string, error) {
-	systemDomains, err := s.store.ListActiveSystemDomains()
+func (s *SystemDomainService) GetAllActiveDomains(ctx context.Context) ([]string, error) {
+	systemDomains, err := s.store.ListActiveSystemDomains(ctx)
 	if err != nil {
 		return nil, err
 	}
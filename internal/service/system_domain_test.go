@@ -0,0 +1,135 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func newDomainTestServices(t *testing.T) (*memory.Store, *SystemDomainService, *UserDomainService) {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	cfg := &config.Config{}
+	cfg.SMTP.Domain = "mail.example.com"
+	return store, NewSystemDomainService(store, cfg), NewUserDomainService(store, cfg)
+}
+
+func saveVerifiedUserDomain(t *testing.T, store *memory.Store, name, userID string) *domain.UserDomain {
+	t.Helper()
+	ud := &domain.UserDomain{
+		ID:           "ud-" + name,
+		UserID:       userID,
+		Domain:       name,
+		Mode:         domain.DomainModeShared,
+		Status:       domain.DomainStatusVerified,
+		IsActive:     true,
+		CreatedAt:    time.Now().UTC(),
+		MailboxCount: 1,
+	}
+	require.NoError(t, store.SaveUserDomain(t.Context(), ud))
+	return ud
+}
+
+func TestDomainOwnershipConflicts(t *testing.T) {
+	t.Run("用户已验证的域名不能添加为系统域名", func(t *testing.T) {
+		store, sysSvc, _ := newDomainTestServices(t)
+		saveVerifiedUserDomain(t, store, "owned.example", "user-1")
+
+		_, err := sysSvc.AddSystemDomain(t.Context(), AddSystemDomainInput{Domain: "owned.example", CreatedBy: "admin"})
+		assert.ErrorIs(t, err, ErrSystemDomainOwnedByUser)
+
+		_, err = sysSvc.RecoverSystemDomain(t.Context(), "owned.example", "admin")
+		assert.ErrorIs(t, err, ErrSystemDomainOwnedByUser)
+	})
+
+	t.Run("系统域名不能被用户添加", func(t *testing.T) {
+		_, sysSvc, userSvc := newDomainTestServices(t)
+		_, err := sysSvc.AddSystemDomain(t.Context(), AddSystemDomainInput{Domain: "system.example", CreatedBy: "admin"})
+		require.NoError(t, err)
+
+		_, err = userSvc.AddDomain(t.Context(), AddDomainInput{UserID: "user-1", Domain: "system.example", Mode: domain.DomainModeShared})
+		assert.ErrorIs(t, err, ErrDomainIsSystem)
+	})
+}
+
+func TestSystemDomainService_ClaimFromUser(t *testing.T) {
+	setup := func(t *testing.T) (*memory.Store, *SystemDomainService) {
+		store, sysSvc, _ := newDomainTestServices(t)
+		saveVerifiedUserDomain(t, store, "claim.example", "user-1")
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+			ID:        "mb-1",
+			Address:   "a@claim.example",
+			LocalPart: "a",
+			Domain:    "claim.example",
+			CreatedAt: time.Now().UTC(),
+		}))
+		return store, sysSvc
+	}
+
+	t.Run("保留策略不修改邮箱过期时间", func(t *testing.T) {
+		store, sysSvc := setup(t)
+
+		result, err := sysSvc.ClaimFromUser(t.Context(), ClaimFromUserInput{Domain: "claim.example", ClaimedBy: "super-1"})
+		require.NoError(t, err)
+		assert.Equal(t, ClaimMailboxKeep, result.MailboxStrategy)
+		assert.Equal(t, "user-1", result.PreviousOwnerID)
+		assert.Equal(t, 1, result.AffectedMailboxes)
+		assert.True(t, result.SystemDomain.IsActive)
+		assert.Contains(t, result.SystemDomain.Notes, "super-1")
+
+		mb, err := store.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Nil(t, mb.ExpiresAt)
+
+		_, err = store.GetUserDomainByDomain(t.Context(), "claim.example")
+		assert.Error(t, err)
+	})
+
+	t.Run("30天过期策略设置邮箱过期时间", func(t *testing.T) {
+		store, sysSvc := setup(t)
+
+		_, err := sysSvc.ClaimFromUser(t.Context(), ClaimFromUserInput{
+			Domain:          "claim.example",
+			ClaimedBy:       "super-1",
+			MailboxStrategy: ClaimMailboxExpireIn30d,
+		})
+		require.NoError(t, err)
+
+		mb, err := store.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+		require.NotNil(t, mb.ExpiresAt)
+		assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), *mb.ExpiresAt, time.Minute)
+	})
+
+	t.Run("无效策略和不存在的用户域名", func(t *testing.T) {
+		_, sysSvc := setup(t)
+
+		_, err := sysSvc.ClaimFromUser(t.Context(), ClaimFromUserInput{Domain: "claim.example", MailboxStrategy: "drop"})
+		assert.ErrorIs(t, err, ErrInvalidClaimStrategy)
+
+		_, err = sysSvc.ClaimFromUser(t.Context(), ClaimFromUserInput{Domain: "missing.example"})
+		assert.ErrorIs(t, err, ErrUserDomainNotFound)
+	})
+
+	t.Run("转换后按系统域名路由", func(t *testing.T) {
+		_, sysSvc := setup(t)
+
+		before := sysSvc.ResolveDomain(t.Context(), "claim.example")
+		assert.Equal(t, DomainKindUser, before.Kind)
+		assert.True(t, before.Managed())
+
+		_, err := sysSvc.ClaimFromUser(t.Context(), ClaimFromUserInput{Domain: "claim.example", ClaimedBy: "super-1"})
+		require.NoError(t, err)
+
+		after := sysSvc.ResolveDomain(t.Context(), "CLAIM.example")
+		assert.Equal(t, DomainKindSystem, after.Kind)
+		assert.True(t, after.Managed())
+		assert.False(t, sysSvc.ResolveDomain(t.Context(), "unknown.example").Managed())
+	})
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -22,10 +23,11 @@ func NewTagService(store domain.Store) *TagService {
 
 // CreateTagInput 创建标签输入
 type CreateTagInput struct {
-	UserID      string `json:"-"` // 从JWT中获取，不需要客户端提供
-	Name        string `json:"name" binding:"required,min=1,max=20"`
-	Color       string `json:"color" binding:"required"`
-	Description string `json:"description" binding:"omitempty,max=100"`
+	UserID      string  `json:"-"`     // 从JWT中获取，不需要客户端提供
+	OrgID       *string `json:"orgId"` // 可选：创建为组织标签（需要组织成员身份）
+	Name        string  `json:"name" binding:"required,min=1,max=20"`
+	Color       string  `json:"color" binding:"required"`
+	Description string  `json:"description" binding:"omitempty,max=100"`
 }
 
 // UpdateTagInput 更新标签输入
@@ -43,9 +45,15 @@ type UpdateTagInput struct {
 // 返回值:
 //   - *domain.Tag: 创建的标签
 //   - error: 错误信息
-func (s *TagService) CreateTag(input CreateTagInput) (*domain.Tag, error) {
+func (s *TagService) CreateTag(ctx context.Context, input CreateTagInput) (*domain.Tag, error) {
+	if domain.InOrg(input.OrgID) {
+		if _, err := NewAuthorizer(s.store).RequireOrgRole(ctx, input.UserID, *input.OrgID, domain.OrgRoleMember); err != nil {
+			return nil, err
+		}
+	}
+
 	// 检查是否已存在同名标签
-	existing, _ := s.store.GetTagByName(input.UserID, input.Name)
+	existing, _ := s.store.GetTagByName(ctx, input.UserID, input.Name)
 	if existing != nil {
 		return nil, fmt.Errorf("tag already exists")
 	}
@@ -53,12 +61,13 @@ func (s *TagService) CreateTag(input CreateTagInput) (*domain.Tag, error) {
 	tag := &domain.Tag{
 		ID:          uuid.New().String(),
 		UserID:      input.UserID,
+		OrgID:       input.OrgID,
 		Name:        input.Name,
 		Color:       input.Color,
 		Description: input.Description,
 	}
 
-	if err := s.store.CreateTag(tag); err != nil {
+	if err := s.store.CreateTag(ctx, tag); err != nil {
 		return nil, err
 	}
 
@@ -73,8 +82,8 @@ func (s *TagService) CreateTag(input CreateTagInput) (*domain.Tag, error) {
 // 返回值:
 //   - *domain.Tag: 标签详情
 //   - error: 错误信息
-func (s *TagService) GetTag(id string) (*domain.Tag, error) {
-	return s.store.GetTag(id)
+func (s *TagService) GetTag(ctx context.Context, id string) (*domain.Tag, error) {
+	return s.store.GetTag(ctx, id)
 }
 
 // ListTags 列出用户的所有标签
@@ -83,10 +92,28 @@ func (s *TagService) GetTag(id string) (*domain.Tag, error) {
 //   - userID: 用户ID
 //
 // 返回值:
-//   - []domain.TagWithCount: 标签列表（含计数）
+//   - []domain.TagWithCount: 标签列表（含计数，包括所在组织的标签）
 //   - error: 错误信息
-func (s *TagService) ListTags(userID string) ([]domain.TagWithCount, error) {
-	return s.store.ListTags(userID)
+func (s *TagService) ListTags(ctx context.Context, userID string) ([]domain.TagWithCount, error) {
+	owned, err := s.store.ListTags(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// 个人标签加上所在组织的标签
+	result := make([]domain.TagWithCount, 0, len(owned))
+	for _, tag := range owned {
+		if !domain.InOrg(tag.OrgID) {
+			result = append(result, tag)
+		}
+	}
+	for _, orgID := range OrgIDsForUser(ctx, s.store, userID) {
+		orgTags, err := s.store.ListTagsByOrgID(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, orgTags...)
+	}
+	return result, nil
 }
 
 // UpdateTag 更新标签
@@ -98,8 +125,8 @@ func (s *TagService) ListTags(userID string) ([]domain.TagWithCount, error) {
 // 返回值:
 //   - *domain.Tag: 更新后的标签
 //   - error: 错误信息
-func (s *TagService) UpdateTag(id string, input UpdateTagInput) (*domain.Tag, error) {
-	tag, err := s.store.GetTag(id)
+func (s *TagService) UpdateTag(ctx context.Context, id string, input UpdateTagInput) (*domain.Tag, error) {
+	tag, err := s.store.GetTag(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +134,7 @@ func (s *TagService) UpdateTag(id string, input UpdateTagInput) (*domain.Tag, er
 	// 更新字段
 	if input.Name != "" {
 		// 检查名称冲突
-		existing, _ := s.store.GetTagByName(tag.UserID, input.Name)
+		existing, _ := s.store.GetTagByName(ctx, tag.UserID, input.Name)
 		if existing != nil && existing.ID != id {
 			return nil, fmt.Errorf("tag name already exists")
 		}
@@ -120,7 +147,7 @@ func (s *TagService) UpdateTag(id string, input UpdateTagInput) (*domain.Tag, er
 		tag.Description = input.Description
 	}
 
-	if err := s.store.UpdateTag(tag); err != nil {
+	if err := s.store.UpdateTag(ctx, tag); err != nil {
 		return nil, err
 	}
 
@@ -134,8 +161,8 @@ func (s *TagService) UpdateTag(id string, input UpdateTagInput) (*domain.Tag, er
 //
 // 返回值:
 //   - error: 错误信息
-func (s *TagService) DeleteTag(id string) error {
-	return s.store.DeleteTag(id)
+func (s *TagService) DeleteTag(ctx context.Context, id string) error {
+	return s.store.DeleteTag(ctx, id)
 }
 
 // AddMessageTag 为邮件添加标签
@@ -146,8 +173,8 @@ func (s *TagService) DeleteTag(id string) error {
 //
 // 返回值:
 //   - error: 错误信息
-func (s *TagService) AddMessageTag(messageID, tagID string) error {
-	return s.store.AddMessageTag(messageID, tagID)
+func (s *TagService) AddMessageTag(ctx context.Context, messageID, tagID string) error {
+	return s.store.AddMessageTag(ctx, messageID, tagID)
 }
 
 // RemoveMessageTag 移除邮件标签
@@ -158,8 +185,8 @@ func (s *TagService) AddMessageTag(messageID, tagID string) error {
 //
 // 返回值:
 //   - error: 错误信息
-func (s *TagService) RemoveMessageTag(messageID, tagID string) error {
-	return s.store.RemoveMessageTag(messageID, tagID)
+func (s *TagService) RemoveMessageTag(ctx context.Context, messageID, tagID string) error {
+	return s.store.RemoveMessageTag(ctx, messageID, tagID)
 }
 
 // GetMessageTags 获取邮件的所有标签
@@ -170,8 +197,8 @@ func (s *TagService) RemoveMessageTag(messageID, tagID string) error {
 // 返回值:
 //   - []domain.Tag: 标签列表
 //   - error: 错误信息
-func (s *TagService) GetMessageTags(messageID string) ([]domain.Tag, error) {
-	return s.store.GetMessageTags(messageID)
+func (s *TagService) GetMessageTags(ctx context.Context, messageID string) ([]domain.Tag, error) {
+	return s.store.GetMessageTags(ctx, messageID)
 }
 
 // ListMessagesByTag 列出标签下的所有邮件
@@ -182,6 +209,6 @@ func (s *TagService) GetMessageTags(messageID string) ([]domain.Tag, error) {
 // 返回值:
 //   - []domain.Message: 邮件列表
 //   - error: 错误信息
-func (s *TagService) ListMessagesByTag(tagID string) ([]domain.Message, error) {
-	return s.store.ListMessagesByTag(tagID)
+func (s *TagService) ListMessagesByTag(ctx context.Context, tagID string) ([]domain.Message, error) {
+	return s.store.ListMessagesByTag(ctx, tagID)
 }
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/security"
+	"tempmail/backend/internal/translate"
+)
+
+var (
+	ErrTranslationNotConfigured = errors.New("translation provider not configured")
+	ErrInvalidTargetLanguage    = errors.New("invalid target language")
+	ErrNothingToTranslate       = errors.New("message has no text to translate")
+)
+
+// MaxCachedTranslations 单封邮件最多缓存的译文数量
+const MaxCachedTranslations = 5
+
+// MaxTranslateRunes 单次翻译的最大字符数（超出部分截断，控制翻译服务费用）
+const MaxTranslateRunes = 20000
+
+// languageCodePattern ISO 639-1 语言代码（可带地区，如 pt-BR）
+var languageCodePattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]{2})?$`)
+
+// TranslationResult 翻译结果
+type TranslationResult struct {
+	MessageID      string `json:"messageId"`
+	SourceLanguage string `json:"sourceLanguage,omitempty"`
+	TargetLanguage string `json:"targetLanguage"`
+	Text           string `json:"text"`
+	Cached         bool   `json:"cached"` // 是否命中缓存
+}
+
+// SetTranslator 设置翻译服务（nil 表示未配置）
+func (s *MessageService) SetTranslator(translator translate.Translator) {
+	s.translator = translator
+}
+
+// Translate 翻译邮件正文
+//
+// HTML 邮件只翻译提取出的纯文本；译文按目标语言缓存在邮件上，重复请求不再调用翻译服务。
+func (s *MessageService) Translate(ctx context.Context, mailboxID, messageID, target string) (*TranslationResult, error) {
+	if s.translator == nil {
+		return nil, ErrTranslationNotConfigured
+	}
+
+	target = strings.ToLower(strings.TrimSpace(target))
+	if !languageCodePattern.MatchString(target) {
+		return nil, ErrInvalidTargetLanguage
+	}
+
+	message, err := s.Get(ctx, mailboxID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TranslationResult{
+		MessageID:      message.ID,
+		SourceLanguage: message.DetectedLanguage,
+		TargetLanguage: target,
+	}
+
+	s.translateMu.Lock()
+	cached, ok := message.TranslatedBodies[target]
+	s.translateMu.Unlock()
+	if ok {
+		result.Text, result.Cached = cached, true
+		return result, nil
+	}
+
+	text := messageBodyText(message)
+	if text == "" {
+		return nil, ErrNothingToTranslate
+	}
+	if runes := []rune(text); len(runes) > MaxTranslateRunes {
+		text = string(runes[:MaxTranslateRunes])
+	}
+
+	translated, err := s.translator.Translate(ctx, translate.Request{
+		Text:   text,
+		Source: message.DetectedLanguage,
+		Target: target,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.SourceLanguage == "" {
+		result.SourceLanguage = translated.SourceLanguage
+	}
+	result.Text = translated.Text
+
+	s.cacheTranslation(message, target, translated.Text)
+	return result, nil
+}
+
+// cacheTranslation 缓存译文（超出上限时淘汰其他语言的一条旧译文）
+func (s *MessageService) cacheTranslation(message *domain.Message, target, text string) {
+	s.translateMu.Lock()
+	defer s.translateMu.Unlock()
+
+	if message.TranslatedBodies == nil {
+		message.TranslatedBodies = make(map[string]string)
+	}
+	for lang := range message.TranslatedBodies {
+		if len(message.TranslatedBodies) < MaxCachedTranslations {
+			break
+		}
+		delete(message.TranslatedBodies, lang)
+	}
+	message.TranslatedBodies[target] = text
+
+	// 内存存储直接持有邮件指针；文件系统存储需回写元数据
+	if s.fsStore == nil {
+		return
+	}
+	metadata, err := s.fsStore.GetMessageMetadata(message.MailboxID, message.ID)
+	if err != nil {
+		return
+	}
+	metadata.TranslatedBodies = message.TranslatedBodies
+	_, _ = s.fsStore.SaveMessageMetadata(message.MailboxID, message.ID, metadata)
+}
+
+// messageBodyText 获取邮件正文纯文本（无纯文本时从 HTML 提取）
+func messageBodyText(message *domain.Message) string {
+	if text := strings.TrimSpace(message.Text); text != "" {
+		return text
+	}
+	if message.HTML != "" {
+		return security.ExtractText(message.HTML)
+	}
+	return ""
+}
+
+// detectLanguage 检测邮件正文语言
+func detectLanguage(subject, text, html string) string {
+	body := text
+	if strings.TrimSpace(body) == "" && html != "" {
+		body = security.ExtractText(html)
+	}
+	if lang := translate.Detect(body); lang != "" {
+		return lang
+	}
+	return translate.Detect(subject + "\n" + body)
+}
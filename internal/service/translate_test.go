@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+	"tempmail/backend/internal/translate"
+)
+
+// fakeTranslator 记录调用次数的翻译服务
+type fakeTranslator struct {
+	calls int
+	last  translate.Request
+	err   error
+}
+
+func (f *fakeTranslator) Name() string { return "fake" }
+
+func (f *fakeTranslator) Translate(ctx context.Context, req translate.Request) (*translate.Result, error) {
+	f.calls++
+	f.last = req
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &translate.Result{Text: "[" + req.Target + "] " + req.Text}, nil
+}
+
+func setupTranslateService(t *testing.T) (*MessageService, string) {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "a@temp.mail", LocalPart: "a", Domain: "temp.mail", CreatedAt: time.Now(),
+	}))
+
+	svc := NewMessageService(store)
+	msg, err := svc.Create(t.Context(), CreateMessageInput{
+		MailboxID: "mb-1",
+		From:      "noreply@example.de",
+		Subject:   "Bestätigen Sie Ihre E-Mail-Adresse",
+		HTML:      "<p>Bitte bestätigen Sie Ihre E-Mail-Adresse, indem Sie auf den folgenden Link klicken.</p><style>p{}</style>",
+	})
+	require.NoError(t, err)
+	return svc, msg.ID
+}
+
+func TestMessageService_DetectLanguage(t *testing.T) {
+	svc, messageID := setupTranslateService(t)
+
+	msg, err := svc.Get(t.Context(), "mb-1", messageID)
+	require.NoError(t, err)
+	assert.Equal(t, "de", msg.DetectedLanguage)
+}
+
+func TestMessageService_Translate(t *testing.T) {
+	t.Run("未配置翻译服务", func(t *testing.T) {
+		svc, messageID := setupTranslateService(t)
+		_, err := svc.Translate(context.Background(), "mb-1", messageID, "en")
+		assert.ErrorIs(t, err, ErrTranslationNotConfigured)
+	})
+
+	t.Run("HTML 邮件翻译提取的文本并命中缓存", func(t *testing.T) {
+		svc, messageID := setupTranslateService(t)
+		fake := &fakeTranslator{}
+		svc.SetTranslator(fake)
+
+		first, err := svc.Translate(context.Background(), "mb-1", messageID, "EN")
+		require.NoError(t, err)
+		assert.False(t, first.Cached)
+		assert.Equal(t, "en", first.TargetLanguage)
+		assert.Equal(t, "de", first.SourceLanguage)
+		assert.NotContains(t, fake.last.Text, "<p>")
+		assert.NotContains(t, fake.last.Text, "p{}")
+
+		second, err := svc.Translate(context.Background(), "mb-1", messageID, "en")
+		require.NoError(t, err)
+		assert.True(t, second.Cached)
+		assert.Equal(t, first.Text, second.Text)
+		assert.Equal(t, 1, fake.calls)
+	})
+
+	t.Run("缓存数量有上限", func(t *testing.T) {
+		svc, messageID := setupTranslateService(t)
+		svc.SetTranslator(&fakeTranslator{})
+
+		for _, lang := range []string{"en", "fr", "es", "it", "pt", "nl", "ja"} {
+			_, err := svc.Translate(context.Background(), "mb-1", messageID, lang)
+			require.NoError(t, err)
+		}
+		msg, err := svc.Get(t.Context(), "mb-1", messageID)
+		require.NoError(t, err)
+		assert.Len(t, msg.TranslatedBodies, MaxCachedTranslations)
+		assert.Contains(t, msg.TranslatedBodies, "ja")
+	})
+
+	t.Run("翻译服务错误透传且不写入缓存", func(t *testing.T) {
+		svc, messageID := setupTranslateService(t)
+		providerErr := &translate.ProviderError{Provider: "fake", StatusCode: 456, Message: "quota exceeded"}
+		fake := &fakeTranslator{err: providerErr}
+		svc.SetTranslator(fake)
+
+		_, err := svc.Translate(context.Background(), "mb-1", messageID, "en")
+		var got *translate.ProviderError
+		require.True(t, errors.As(err, &got))
+		assert.Equal(t, 456, got.StatusCode)
+
+		fake.err = nil
+		result, err := svc.Translate(context.Background(), "mb-1", messageID, "en")
+		require.NoError(t, err)
+		assert.False(t, result.Cached)
+		assert.Equal(t, 2, fake.calls)
+	})
+
+	t.Run("无效目标语言", func(t *testing.T) {
+		svc, messageID := setupTranslateService(t)
+		svc.SetTranslator(&fakeTranslator{})
+		_, err := svc.Translate(context.Background(), "mb-1", messageID, "english")
+		assert.ErrorIs(t, err, ErrInvalidTargetLanguage)
+	})
+}
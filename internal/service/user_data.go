@@ -0,0 +1,408 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"tempmail/backend/internal/domain"
+)
+
+var ErrExportRateLimited = errors.New("data export rate limited")
+
+const (
+	// UserDataExportSchema 个人数据导出文档标识
+	UserDataExportSchema = "tempmail.user-data-export"
+	// UserDataExportVersion 个人数据导出文档版本（结构不兼容变更时递增）
+	UserDataExportVersion = 1
+	// UserDataExportInterval 同一用户两次导出的最小间隔
+	UserDataExportInterval = time.Hour
+	// UserDataExportFile 导出压缩包中的 JSON 文件名
+	UserDataExportFile = "export.json"
+
+	// aggregationConcurrency 聚合时并发访问存储的上限
+	aggregationConcurrency = 4
+	// maskedSecret 导出中代替密钥、令牌的占位值
+	maskedSecret = "********"
+)
+
+// 数据访问审计事件
+const (
+	AuditUserDataExported = "user_data_exported"
+	AuditRetentionReport  = "retention_report_generated"
+)
+
+// DataAuditFunc 数据访问审计回调；subjectID 为被导出数据的用户（报告为空）
+type DataAuditFunc func(event, actorID, subjectID string)
+
+// messageReader 读取单封邮件的完整内容（文件系统存储的正文不在数据库中）
+type messageReader interface {
+	Get(ctx context.Context, mailboxID, messageID string) (*domain.Message, error)
+}
+
+// UserDataExport 用户个人数据导出文档
+//
+// 包含与用户关联的全部数据：资料、邮箱、邮件头信息（正文仅在 includeBodies 时导出）、域名、
+// Webhook、标签、API Key 元数据、组织成员关系和分发列表。邮箱令牌、Webhook 密钥和 API Key
+// 哈希一律遮盖。审计事件写入应用日志而非存储，因此不在导出范围内。
+type UserDataExport struct {
+	Schema            string                     `json:"schema"`
+	Version           int                        `json:"version"`
+	ExportedAt        time.Time                  `json:"exportedAt"`
+	IncludesBodies    bool                       `json:"includesBodies"`
+	Profile           ExportProfile              `json:"profile"`
+	Mailboxes         []ExportMailbox            `json:"mailboxes"`
+	Messages          []ExportMessage            `json:"messages"`
+	Domains           []*domain.UserDomain       `json:"domains"`
+	Webhooks          []domain.Webhook           `json:"webhooks"`
+	Tags              []domain.TagWithCount      `json:"tags"`
+	APIKeys           []ExportAPIKey             `json:"apiKeys"`
+	Organizations     []*domain.OrgMember        `json:"organizations"`
+	DistributionLists []*domain.DistributionList `json:"distributionLists"`
+}
+
+// ExportProfile 用户资料（不含密码哈希和登录 IP）
+type ExportProfile struct {
+	ID              string          `json:"id"`
+	Email           string          `json:"email"`
+	Username        string          `json:"username,omitempty"`
+	Role            domain.UserRole `json:"role"`
+	Tier            domain.UserTier `json:"tier"`
+	IsActive        bool            `json:"isActive"`
+	IsEmailVerified bool            `json:"isEmailVerified"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+	LastLoginAt     *time.Time      `json:"lastLoginAt,omitempty"`
+}
+
+// ExportMailbox 邮箱元数据（令牌已遮盖）
+type ExportMailbox struct {
+	domain.Mailbox
+	MessageCount int `json:"messageCount"`
+}
+
+// ExportMessage 邮件头信息，正文和附件元数据仅在 includeBodies 时填充
+type ExportMessage struct {
+	ID              string             `json:"id"`
+	MailboxID       string             `json:"mailboxId"`
+	From            string             `json:"from"`
+	To              string             `json:"to"`
+	Subject         string             `json:"subject"`
+	ReceivedAt      time.Time          `json:"receivedAt"`
+	IsRead          bool               `json:"isRead"`
+	Size            int64              `json:"size"`
+	Quarantined     bool               `json:"quarantined"`
+	CapturedHeaders map[string]string  `json:"capturedHeaders,omitempty"`
+	Text            string             `json:"text,omitempty"`
+	HTML            string             `json:"html,omitempty"`
+	Attachments     []ExportAttachment `json:"attachments,omitempty"`
+}
+
+// ExportAttachment 附件元数据（不含内容）
+type ExportAttachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// ExportAPIKey API Key 元数据（不含密钥哈希）
+type ExportAPIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	KeyPrefix  string     `json:"keyPrefix"`
+	Scopes     *string    `json:"scopes,omitempty"`
+	IsActive   bool       `json:"isActive"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// UserDataService 按用户聚合存储数据（个人数据导出）
+type UserDataService struct {
+	store    domain.Store
+	messages messageReader
+	audit    DataAuditFunc
+	now      func() time.Time
+
+	mu          sync.Mutex
+	lastExports map[string]time.Time // 用户最近一次导出时间
+}
+
+// NewUserDataService 创建个人数据服务
+func NewUserDataService(store domain.Store) *UserDataService {
+	return &UserDataService{
+		store:       store,
+		now:         time.Now,
+		lastExports: make(map[string]time.Time),
+	}
+}
+
+// SetMessageReader 设置读取完整邮件的服务（导出正文时使用，未设置时直接读存储）
+func (s *UserDataService) SetMessageReader(messages messageReader) {
+	s.messages = messages
+}
+
+// SetAuditFunc 设置审计回调
+func (s *UserDataService) SetAuditFunc(fn DataAuditFunc) {
+	s.audit = fn
+}
+
+// NextExportAt 用户下一次允许导出的时间（零值表示现在即可导出）
+func (s *UserDataService) NextExportAt(userID string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	last, ok := s.lastExports[userID]
+	if !ok || !s.now().Before(last.Add(UserDataExportInterval)) {
+		return time.Time{}
+	}
+	return last.Add(UserDataExportInterval)
+}
+
+// Export 导出用户的个人数据，每个用户每小时最多一次
+//
+// 只按用户 ID 查询各存储，不会包含其他用户的数据；聚合失败（包括 ctx 取消）时不占用导出次数。
+func (s *UserDataService) Export(ctx context.Context, userID string, includeBodies bool) (*UserDataExport, error) {
+	now := s.now()
+	s.mu.Lock()
+	if last, ok := s.lastExports[userID]; ok && now.Before(last.Add(UserDataExportInterval)) {
+		s.mu.Unlock()
+		return nil, ErrExportRateLimited
+	}
+	previous, hadPrevious := s.lastExports[userID]
+	s.lastExports[userID] = now
+	s.mu.Unlock()
+
+	export, err := s.collect(ctx, userID, includeBodies, now)
+	if err != nil {
+		s.mu.Lock()
+		if hadPrevious {
+			s.lastExports[userID] = previous
+		} else {
+			delete(s.lastExports, userID)
+		}
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	if s.audit != nil {
+		s.audit(AuditUserDataExported, userID, userID)
+	}
+	return export, nil
+}
+
+// collect 并发读取各存储中属于用户的数据
+func (s *UserDataService) collect(ctx context.Context, userID string, includeBodies bool, now time.Time) (*UserDataExport, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &UserDataExport{
+		Schema:         UserDataExportSchema,
+		Version:        UserDataExportVersion,
+		ExportedAt:     now,
+		IncludesBodies: includeBodies,
+		Profile: ExportProfile{
+			ID:              user.ID,
+			Email:           user.Email,
+			Username:        user.Username,
+			Role:            user.Role,
+			Tier:            user.Tier,
+			IsActive:        user.IsActive,
+			IsEmailVerified: user.IsEmailVerified,
+			CreatedAt:       user.CreatedAt,
+			UpdatedAt:       user.UpdatedAt,
+			LastLoginAt:     user.LastLoginAt,
+		},
+	}
+
+	var mailboxes []domain.Mailbox
+	var byMailbox map[string][]domain.Message
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(aggregationConcurrency)
+	g.Go(func() error {
+		mailboxes = ownedMailboxes(s.store.ListMailboxesByUserID(gctx, userID), userID)
+		var err error
+		byMailbox, err = listMessagesBounded(gctx, s.store, mailboxes)
+		return err
+	})
+	g.Go(func() (err error) {
+		export.Domains, err = s.store.ListUserDomainsByUserID(ctx, userID)
+		return err
+	})
+	g.Go(func() (err error) {
+		export.Webhooks, err = s.store.ListWebhooks(gctx, userID)
+		return err
+	})
+	g.Go(func() (err error) {
+		export.Tags, err = s.store.ListTags(ctx, userID)
+		return err
+	})
+	g.Go(func() error {
+		keys, err := s.store.ListAPIKeysByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if key.UserID != userID {
+				continue
+			}
+			export.APIKeys = append(export.APIKeys, ExportAPIKey{
+				ID:         key.ID,
+				Name:       key.Name,
+				KeyPrefix:  key.KeyPrefix,
+				Scopes:     key.Scopes,
+				IsActive:   key.IsActive,
+				CreatedAt:  key.CreatedAt,
+				ExpiresAt:  key.ExpiresAt,
+				LastUsedAt: key.LastUsedAt,
+			})
+		}
+		return nil
+	})
+	g.Go(func() (err error) {
+		export.Organizations, err = s.store.ListOrgMembershipsByUserID(ctx, userID)
+		return err
+	})
+	g.Go(func() (err error) {
+		export.DistributionLists, err = s.store.ListDistributionListsByUserID(ctx, userID)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	export.Mailboxes = make([]ExportMailbox, 0, len(mailboxes))
+	export.Messages = make([]ExportMessage, 0)
+	for _, mb := range mailboxes {
+		mb.Token = maskedSecret
+		msgs := byMailbox[mb.ID]
+		export.Mailboxes = append(export.Mailboxes, ExportMailbox{Mailbox: mb, MessageCount: len(msgs)})
+		for i := range msgs {
+			export.Messages = append(export.Messages, s.exportMessage(ctx, &msgs[i], includeBodies))
+		}
+	}
+	for i := range export.Webhooks {
+		if export.Webhooks[i].Secret != "" {
+			export.Webhooks[i].Secret = maskedSecret
+		}
+	}
+	sort.Slice(export.APIKeys, func(i, j int) bool { return export.APIKeys[i].CreatedAt.Before(export.APIKeys[j].CreatedAt) })
+	return export, nil
+}
+
+// exportMessage 转换邮件；导出正文时读取完整内容，读取失败则只保留头信息
+func (s *UserDataService) exportMessage(ctx context.Context, msg *domain.Message, includeBodies bool) ExportMessage {
+	out := ExportMessage{
+		ID:              msg.ID,
+		MailboxID:       msg.MailboxID,
+		From:            msg.From,
+		To:              msg.To,
+		Subject:         msg.Subject,
+		ReceivedAt:      msg.ReceivedAt,
+		IsRead:          msg.IsRead,
+		Size:            msg.Size,
+		Quarantined:     msg.Quarantined,
+		CapturedHeaders: msg.CapturedHeaders,
+	}
+	if !includeBodies {
+		return out
+	}
+
+	full := msg
+	if s.messages != nil {
+		if loaded, err := s.messages.Get(ctx, msg.MailboxID, msg.ID); err == nil {
+			full = loaded
+		}
+	}
+	out.Text, out.HTML = full.Text, full.HTML
+	for _, att := range full.Attachments {
+		out.Attachments = append(out.Attachments, ExportAttachment{
+			ID:          att.ID,
+			Filename:    att.Filename,
+			ContentType: att.ContentType,
+			Size:        att.Size,
+		})
+	}
+	return out
+}
+
+// WriteArchive 把导出文档写成 zip 压缩包（流式写入，ctx 取消后立即停止写入）
+func WriteArchive(ctx context.Context, w io.Writer, export *UserDataExport) error {
+	archive := zip.NewWriter(&contextWriter{ctx: ctx, w: w})
+	file, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     UserDataExportFile,
+		Method:   zip.Deflate,
+		Modified: export.ExportedAt,
+	})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// contextWriter 在每次写入前检查 ctx，客户端断开后不再继续编码和写出
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w *contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
+// ownedMailboxes 只保留属于用户的邮箱（防御存储实现返回多余数据）
+func ownedMailboxes(mailboxes []domain.Mailbox, userID string) []domain.Mailbox {
+	owned := mailboxes[:0]
+	for _, mb := range mailboxes {
+		if mb.UserID != nil && *mb.UserID == userID {
+			owned = append(owned, mb)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool { return owned[i].CreatedAt.Before(owned[j].CreatedAt) })
+	return owned
+}
+
+// listMessagesBounded 以有限并发读取多个邮箱的邮件，结果按邮箱 ID 索引
+func listMessagesBounded(ctx context.Context, store domain.Store, mailboxes []domain.Mailbox) (map[string][]domain.Message, error) {
+	var mu sync.Mutex
+	result := make(map[string][]domain.Message, len(mailboxes))
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(aggregationConcurrency)
+	for _, mb := range mailboxes {
+		mailboxID := mb.ID
+		g.Go(func() error {
+			msgs, err := store.ListMessages(ctx, mailboxID)
+			if err != nil {
+				return err
+			}
+			sort.Slice(msgs, func(i, j int) bool { return msgs[i].ReceivedAt.Before(msgs[j].ReceivedAt) })
+			mu.Lock()
+			result[mailboxID] = msgs
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// seedUserData 为用户创建每种数据各一条
+func seedUserData(t *testing.T, store *memory.Store, userID, prefix string, now time.Time) {
+	t.Helper()
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{
+		ID: userID, Email: prefix + "@example.com", Username: prefix, PasswordHash: "hash-" + prefix,
+		Role: domain.RoleUser, Tier: domain.TierFree, IsActive: true, CreatedAt: now, UpdatedAt: now,
+	}))
+	expires := now.Add(time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: prefix + "-mb", Address: prefix + "@temp.mail", LocalPart: prefix, Domain: "temp.mail",
+		Token: "token-" + prefix, UserID: &userID, CreatedAt: now, ExpiresAt: &expires,
+	}))
+	require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+		ID: prefix + "-msg", MailboxID: prefix + "-mb", From: "sender@example.com", Subject: "hello " + prefix,
+		Text: "body of " + prefix, ReceivedAt: now, CreatedAt: now,
+	}))
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{ID: prefix + "-dom", UserID: userID, Domain: prefix + ".example.org", CreatedAt: now}))
+	require.NoError(t, store.CreateWebhook(t.Context(), &domain.Webhook{ID: prefix + "-wh", UserID: userID, URL: "https://hooks.example.com/" + prefix, Secret: "whsec-" + prefix, CreatedAt: now}))
+	require.NoError(t, store.CreateTag(t.Context(), &domain.Tag{ID: prefix + "-tag", UserID: userID, Name: "tag-" + prefix, CreatedAt: now}))
+	require.NoError(t, store.SaveAPIKey(t.Context(), &domain.APIKey{ID: prefix + "-key", UserID: userID, Key: "keyhash-" + prefix, KeyPrefix: "tm_" + prefix, Name: "ci", IsActive: true, CreatedAt: now}))
+}
+
+func TestUserDataService_Export(t *testing.T) {
+	now := time.Now()
+	setup := func(t *testing.T) *UserDataService {
+		store := memory.NewStore(24 * time.Hour)
+		seedUserData(t, store, "user-1", "alice", now)
+		seedUserData(t, store, "user-2", "bob", now)
+		return NewUserDataService(store)
+	}
+
+	t.Run("压缩包包含用户的全部数据且遮盖密钥", func(t *testing.T) {
+		svc := setup(t)
+		var audited []string
+		svc.SetAuditFunc(func(event, actorID, subjectID string) { audited = append(audited, event+":"+actorID+":"+subjectID) })
+
+		export, err := svc.Export(t.Context(), "user-1", false)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, WriteArchive(t.Context(), &buf, export))
+
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		require.Len(t, archive.File, 1)
+		assert.Equal(t, UserDataExportFile, archive.File[0].Name)
+		f, err := archive.File[0].Open()
+		require.NoError(t, err)
+		raw, err := io.ReadAll(f)
+		require.NoError(t, err)
+
+		var doc map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(raw, &doc))
+		for _, key := range []string{"schema", "version", "exportedAt", "profile", "mailboxes", "messages", "domains", "webhooks", "tags", "apiKeys", "organizations", "distributionLists"} {
+			assert.Contains(t, doc, key)
+		}
+
+		text := string(raw)
+		assert.Contains(t, text, `"schema": "tempmail.user-data-export"`)
+		assert.Contains(t, text, "hello alice")
+		assert.Contains(t, text, "alice.example.org")
+		assert.Contains(t, text, "tag-alice")
+		assert.Contains(t, text, "tm_alice")
+		for _, secret := range []string{"whsec-alice", "token-alice", "keyhash-alice", "hash-alice", "body of alice"} {
+			assert.NotContains(t, text, secret)
+		}
+		assert.NotContains(t, text, "bob", "不能包含其他用户的数据")
+
+		require.Len(t, export.Webhooks, 1)
+		assert.Equal(t, maskedSecret, export.Webhooks[0].Secret)
+		assert.Equal(t, []string{AuditUserDataExported + ":user-1:user-1"}, audited)
+	})
+
+	t.Run("includeBodies 时包含正文", func(t *testing.T) {
+		export, err := setup(t).Export(t.Context(), "user-1", true)
+		require.NoError(t, err)
+		require.Len(t, export.Messages, 1)
+		assert.Equal(t, "body of alice", export.Messages[0].Text)
+	})
+
+	t.Run("每小时最多导出一次", func(t *testing.T) {
+		svc := setup(t)
+		clock := now
+		svc.now = func() time.Time { return clock }
+
+		_, err := svc.Export(t.Context(), "user-1", false)
+		require.NoError(t, err)
+		_, err = svc.Export(t.Context(), "user-1", false)
+		assert.ErrorIs(t, err, ErrExportRateLimited)
+		assert.Equal(t, clock.Add(UserDataExportInterval), svc.NextExportAt("user-1"))
+
+		_, err = svc.Export(t.Context(), "user-2", false)
+		assert.NoError(t, err, "限流按用户计算")
+
+		clock = clock.Add(UserDataExportInterval)
+		_, err = svc.Export(t.Context(), "user-1", false)
+		assert.NoError(t, err)
+	})
+
+	t.Run("请求取消后停止写出压缩包", func(t *testing.T) {
+		export, err := setup(t).Export(t.Context(), "user-1", true)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		var buf bytes.Buffer
+		assert.ErrorIs(t, WriteArchive(ctx, &buf, export), context.Canceled)
+		assert.Zero(t, buf.Len())
+	})
+
+	t.Run("用户不存在时不占用导出次数", func(t *testing.T) {
+		svc := setup(t)
+		_, err := svc.Export(t.Context(), "missing", false)
+		require.Error(t, err)
+		assert.True(t, svc.NextExportAt("missing").IsZero())
+	})
+}
+
+func TestRetentionService_Report(t *testing.T) {
+	start := time.Now()
+	store := memory.NewStore(0)
+
+	seedUserData(t, store, "user-1", "alice", start)
+	longLived := start.Add(30 * 24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "long-mb", Address: "long@temp.mail", LocalPart: "long", Domain: "temp.mail", CreatedAt: start, ExpiresAt: &longLived,
+	}))
+	require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{ID: "old-msg", MailboxID: "long-mb", ReceivedAt: start.Add(-10 * 24 * time.Hour), CreatedAt: start}))
+	require.NoError(t, store.SaveSystemDomain(t.Context(), &domain.SystemDomain{ID: "sd-1", Domain: "pending.example", Status: domain.SystemDomainStatusPending, CreatedAt: start}))
+
+	svc := NewRetentionService(store)
+	var audited []string
+	svc.SetAuditFunc(func(event, actorID, subjectID string) { audited = append(audited, event+":"+actorID) })
+
+	byCategory := func(report *RetentionReport) map[string]RetentionCategory {
+		categories := make(map[string]RetentionCategory)
+		for _, c := range report.Categories {
+			categories[c.Category] = c
+		}
+		return categories
+	}
+	counts := func(c RetentionCategory) map[string]int {
+		result := make(map[string]int)
+		for _, b := range c.Buckets {
+			result[b.Age] = b.Count
+		}
+		return result
+	}
+
+	t.Run("保留期限内的数据满足要求", func(t *testing.T) {
+		svc.now = func() time.Time { return start.Add(30 * time.Minute) }
+		report, err := svc.Report(t.Context(), "admin-1")
+		require.NoError(t, err)
+		categories := byCategory(report)
+
+		mailboxes := categories[RetentionCategoryMailboxes]
+		assert.Equal(t, 2, mailboxes.Total)
+		assert.Equal(t, 2, counts(mailboxes)["<1h"])
+		require.NotNil(t, mailboxes.Satisfied)
+		assert.True(t, *mailboxes.Satisfied)
+
+		messages := categories[RetentionCategoryMessages]
+		assert.Equal(t, 2, messages.Total)
+		assert.Equal(t, 1, counts(messages)["7d-30d"])
+		require.NotNil(t, messages.Oldest)
+		assert.True(t, messages.Oldest.Equal(start.Add(-10*24*time.Hour)))
+
+		assert.Nil(t, categories[RetentionCategoryUsers].Satisfied, "没有自动删除策略")
+		assert.Equal(t, 1, categories[RetentionCategoryUsers].Total)
+		assert.True(t, *categories[RetentionCategorySystemDomains].Satisfied)
+	})
+
+	t.Run("超过保留期限未删除的数据计为逾期", func(t *testing.T) {
+		svc.now = func() time.Time { return start.Add(3 * time.Hour) }
+		report, err := svc.Report(t.Context(), "admin-1")
+		require.NoError(t, err)
+		categories := byCategory(report)
+
+		mailboxes := categories[RetentionCategoryMailboxes]
+		assert.Equal(t, 2, counts(mailboxes)["1h-24h"])
+		assert.Equal(t, 1, mailboxes.Overdue)
+		assert.False(t, *mailboxes.Satisfied)
+
+		messages := categories[RetentionCategoryMessages]
+		assert.Equal(t, 1, messages.Overdue, "随逾期邮箱保留的邮件")
+		assert.False(t, *messages.Satisfied)
+
+		svc.now = func() time.Time { return start.Add(26 * time.Hour) }
+		report, err = svc.Report(t.Context(), "admin-1")
+		require.NoError(t, err)
+		domains := byCategory(report)[RetentionCategorySystemDomains]
+		assert.Equal(t, 1, counts(domains)["1d-7d"])
+		assert.False(t, *domains.Satisfied)
+	})
+
+	assert.Equal(t, []string{AuditRetentionReport + ":admin-1", AuditRetentionReport + ":admin-1", AuditRetentionReport + ":admin-1"}, audited)
+}
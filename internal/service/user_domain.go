@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
@@ -23,12 +24,18 @@ var (
 	ErrNotDomainOwner      = errors.New("not domain owner")
 	ErrDomainExclusiveMode = errors.New("domain is in exclusive mode")
 	ErrInvalidDomain       = errors.New("invalid domain")
+	ErrDomainIsSystem      = errors.New("domain is managed as a system domain")
+	ErrDomainExpired       = errors.New("domain expired")
+	ErrInvalidExpiry       = errors.New("expiry must be in the future")
 )
 
 // UserDomainService 用户域名服务
 type UserDomainService struct {
 	store domain.Store
 	cfg   *config.Config
+	authz *Authorizer
+
+	webhook *WebhookService // 可选：验证成功后触发 domain.verified
 }
 
 // NewUserDomainService 创建用户域名服务
@@ -36,18 +43,31 @@ func NewUserDomainService(store domain.Store, cfg *config.Config) *UserDomainSer
 	return &UserDomainService{
 		store: store,
 		cfg:   cfg,
+		authz: NewAuthorizer(store),
 	}
 }
 
+// SetWebhookService 设置 Webhook 服务（用于域名验证通知）
+func (s *UserDomainService) SetWebhookService(webhook *WebhookService) {
+	s.webhook = webhook
+}
+
 // AddDomainInput 添加域名输入
 type AddDomainInput struct {
 	UserID string
+	OrgID  *string // 可选：添加为组织域名（需要组织 owner 角色）
 	Domain string
 	Mode   domain.DomainMode
 }
 
 // AddDomain 添加用户域名
-func (s *UserDomainService) AddDomain(input AddDomainInput) (*domain.UserDomain, error) {
+func (s *UserDomainService) AddDomain(ctx context.Context, input AddDomainInput) (*domain.UserDomain, error) {
+	if domain.InOrg(input.OrgID) {
+		if _, err := s.authz.RequireOrgRole(ctx, input.UserID, *input.OrgID, domain.OrgRoleOwner); err != nil {
+			return nil, err
+		}
+	}
+
 	// 验证域名格式
 	domainName := strings.TrimSpace(strings.ToLower(input.Domain))
 	if !isValidDomain(domainName) {
@@ -55,11 +75,16 @@ func (s *UserDomainService) AddDomain(input AddDomainInput) (*domain.UserDomain,
 	}
 
 	// 检查域名是否已存在
-	_, err := s.store.GetUserDomainByDomain(domainName)
+	_, err := s.store.GetUserDomainByDomain(ctx, domainName)
 	if err == nil {
 		return nil, ErrDomainAlreadyExists
 	}
 
+	// 系统域名不允许被用户占用
+	if res := ResolveDomain(ctx, s.store, domainName); res.Kind == DomainKindSystem {
+		return nil, ErrDomainIsSystem
+	}
+
 	// 生成验证令牌
 	verifyToken := generateToken(32)
 
@@ -68,14 +93,15 @@ func (s *UserDomainService) AddDomain(input AddDomainInput) (*domain.UserDomain,
 
 	// 计算月费
 	monthlyFee := 0.0
-	if input.Mode == domain.DomainModeExclusive {
-		monthlyFee = 9.99 // 独享模式月费
+	if input.Mode == domain.DomainModeExclusive || input.Mode == domain.DomainModeCatchAll {
+		monthlyFee = 9.99 // 独享模式月费（通配模式同样只有所有者可以创建邮箱）
 	}
 
 	now := time.Now().UTC()
 	userDomain := &domain.UserDomain{
 		ID:           uuid.NewString(),
 		UserID:       input.UserID,
+		OrgID:        input.OrgID,
 		Domain:       domainName,
 		Mode:         input.Mode,
 		Status:       domain.DomainStatusPending,
@@ -86,9 +112,10 @@ func (s *UserDomainService) AddDomain(input AddDomainInput) (*domain.UserDomain,
 		IsActive:     false, // 需要验证后才激活
 		MailboxCount: 0,
 		MonthlyFee:   monthlyFee,
+		CatchAll:     domain.CatchAllSettings{Enabled: input.Mode == domain.DomainModeCatchAll},
 	}
 
-	if err := s.store.SaveUserDomain(userDomain); err != nil {
+	if err := s.store.SaveUserDomain(ctx, userDomain); err != nil {
 		return nil, err
 	}
 
@@ -96,14 +123,14 @@ func (s *UserDomainService) AddDomain(input AddDomainInput) (*domain.UserDomain,
 }
 
 // VerifyDomain 验证域名所有权
-func (s *UserDomainService) VerifyDomain(domainID, userID string) (*domain.UserDomain, error) {
-	userDomain, err := s.store.GetUserDomain(domainID)
+func (s *UserDomainService) VerifyDomain(ctx context.Context, domainID, userID string) (*domain.UserDomain, error) {
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
 	if err != nil {
 		return nil, ErrDomainNotFound
 	}
 
 	// 检查权限
-	if userDomain.UserID != userID {
+	if !s.authz.Can(ctx, userID, userDomain.UserID, userDomain.OrgID, ActionManage) {
 		return nil, ErrNotDomainOwner
 	}
 
@@ -120,7 +147,7 @@ func (s *UserDomainService) VerifyDomain(domainID, userID string) (*domain.UserD
 		now := time.Now().UTC()
 		userDomain.Status = domain.DomainStatusFailed
 		userDomain.LastCheckAt = &now
-		s.store.SaveUserDomain(userDomain)
+		s.store.SaveUserDomain(ctx, userDomain)
 		return nil, ErrDomainVerifyFailed
 	}
 
@@ -131,42 +158,72 @@ func (s *UserDomainService) VerifyDomain(domainID, userID string) (*domain.UserD
 	userDomain.LastCheckAt = &now
 	userDomain.IsActive = true
 
-	if err := s.store.SaveUserDomain(userDomain); err != nil {
+	if err := s.store.SaveUserDomain(ctx, userDomain); err != nil {
 		return nil, err
 	}
 
+	if s.webhook != nil {
+		s.webhook.NotifyDomainVerified(userDomain)
+	}
+
 	return userDomain, nil
 }
 
 // GetUserDomain 获取用户域名
-func (s *UserDomainService) GetUserDomain(domainID, userID string) (*domain.UserDomain, error) {
-	userDomain, err := s.store.GetUserDomain(domainID)
+func (s *UserDomainService) GetUserDomain(ctx context.Context, domainID, userID string) (*domain.UserDomain, error) {
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
 	if err != nil {
 		return nil, ErrDomainNotFound
 	}
 
 	// 检查权限
-	if userDomain.UserID != userID {
+	if !s.authz.Can(ctx, userID, userDomain.UserID, userDomain.OrgID, ActionRead) {
 		return nil, ErrNotDomainOwner
 	}
 
 	return userDomain, nil
 }
 
-// ListUserDomains 获取用户的所有域名
-func (s *UserDomainService) ListUserDomains(userID string) ([]*domain.UserDomain, error) {
-	return s.store.ListUserDomainsByUserID(userID)
+// ListUserDomains 获取用户的所有域名（个人域名及所在组织的域名）
+func (s *UserDomainService) ListUserDomains(ctx context.Context, userID string) ([]*domain.UserDomain, error) {
+	owned, err := s.store.ListUserDomainsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{})
+	result := make([]*domain.UserDomain, 0, len(owned))
+	for _, d := range owned {
+		if domain.InOrg(d.OrgID) {
+			continue // 组织域名按当前成员关系列出
+		}
+		seen[d.ID] = struct{}{}
+		result = append(result, d)
+	}
+	for _, orgID := range OrgIDsForUser(ctx, s.store, userID) {
+		orgDomains, err := s.store.ListUserDomainsByOrgID(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range orgDomains {
+			if _, ok := seen[d.ID]; ok {
+				continue
+			}
+			seen[d.ID] = struct{}{}
+			result = append(result, d)
+		}
+	}
+	return result, nil
 }
 
 // DeleteUserDomain 删除用户域名
-func (s *UserDomainService) DeleteUserDomain(domainID, userID string) error {
-	userDomain, err := s.store.GetUserDomain(domainID)
+func (s *UserDomainService) DeleteUserDomain(ctx context.Context, domainID, userID string) error {
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
 	if err != nil {
 		return ErrDomainNotFound
 	}
 
 	// 检查权限
-	if userDomain.UserID != userID {
+	if !s.authz.Can(ctx, userID, userDomain.UserID, userDomain.OrgID, ActionManage) {
 		return ErrNotDomainOwner
 	}
 
@@ -175,63 +232,98 @@ func (s *UserDomainService) DeleteUserDomain(domainID, userID string) error {
 		return errors.New("cannot delete domain with active mailboxes")
 	}
 
-	return s.store.DeleteUserDomain(domainID)
+	return s.store.DeleteUserDomain(ctx, domainID)
 }
 
 // UpdateDomainMode 更新域名模式（共享/独享）
-func (s *UserDomainService) UpdateDomainMode(domainID, userID string, mode domain.DomainMode) (*domain.UserDomain, error) {
-	userDomain, err := s.store.GetUserDomain(domainID)
+func (s *UserDomainService) UpdateDomainMode(ctx context.Context, domainID, userID string, mode domain.DomainMode) (*domain.UserDomain, error) {
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
 	if err != nil {
 		return nil, ErrDomainNotFound
 	}
 
 	// 检查权限
-	if userDomain.UserID != userID {
+	if !s.authz.Can(ctx, userID, userDomain.UserID, userDomain.OrgID, ActionManage) {
 		return nil, ErrNotDomainOwner
 	}
 
-	// 更新模式
+	// 更新模式（通配收件随通配模式开关，切换回来时保留通配邮箱设置）
 	userDomain.Mode = mode
-	if mode == domain.DomainModeExclusive {
+	userDomain.CatchAll.Enabled = mode == domain.DomainModeCatchAll
+	if mode == domain.DomainModeExclusive || mode == domain.DomainModeCatchAll {
 		userDomain.MonthlyFee = 9.99
 	} else {
 		userDomain.MonthlyFee = 0
 	}
 
-	if err := s.store.SaveUserDomain(userDomain); err != nil {
+	if err := s.store.SaveUserDomain(ctx, userDomain); err != nil {
 		return nil, err
 	}
 
 	return userDomain, nil
 }
 
-// CanCreateMailboxOnDomain 检查用户是否可以在该域名下创建邮箱
-func (s *UserDomainService) CanCreateMailboxOnDomain(domainName string, userID *string) (bool, error) {
-	// 尝试获取用户域名
-	userDomain, err := s.store.GetUserDomainByDomain(domainName)
+// RenewDomain 续期用户域名（仅所有者，组织域名需要 owner 角色）
+func (s *UserDomainService) RenewDomain(ctx context.Context, domainID, userID string, expiresAt time.Time) (*domain.UserDomain, error) {
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
 	if err != nil {
-		// 域名不存在，说明是系统默认域名，允许创建
-		return true, nil
+		return nil, ErrDomainNotFound
 	}
 
-	// 检查域名状态
-	if !userDomain.IsActive || userDomain.Status != domain.DomainStatusVerified {
-		return false, ErrDomainNotVerified
+	// 检查权限
+	if !s.authz.Can(ctx, userID, userDomain.UserID, userDomain.OrgID, ActionManage) {
+		return nil, ErrNotDomainOwner
+	}
+
+	return s.ExtendDomain(ctx, domainID, expiresAt)
+}
+
+// ExtendDomain 设置用户域名的到期时间（续期/计费回调使用，不检查权限）
+//
+// 续期后立即恢复收信和新建邮箱，并清空宽限期通知状态。
+func (s *UserDomainService) ExtendDomain(ctx context.Context, domainID string, expiresAt time.Time) (*domain.UserDomain, error) {
+	if !expiresAt.After(domainPolicy.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
+	if err != nil {
+		return nil, ErrDomainNotFound
 	}
 
-	// 检查是否过期
-	if userDomain.ExpiresAt != nil && time.Now().After(*userDomain.ExpiresAt) {
-		return false, errors.New("domain expired")
+	expiresAt = expiresAt.UTC()
+	userDomain.ExpiresAt = &expiresAt
+	userDomain.GraceNotices = 0
+	if err := s.store.SaveUserDomain(ctx, userDomain); err != nil {
+		return nil, err
+	}
+	return userDomain, nil
+}
+
+// CanCreateMailboxOnDomain 检查用户是否可以在该域名下创建邮箱
+func (s *UserDomainService) CanCreateMailboxOnDomain(ctx context.Context, domainName string, userID *string) (bool, error) {
+	res := ResolveDomain(ctx, s.store, domainName)
+	if res.Kind != DomainKindUser {
+		// 不是用户域名，说明是系统域名或配置的默认域名，允许创建
+		return true, nil
+	}
+	userDomain := res.UserDomain
+
+	// 检查域名状态（过期后宽限期内仍收信，但不能新建邮箱）
+	switch res.State {
+	case DomainStateInactive:
+		return false, ErrDomainNotVerified
+	case DomainStateGrace, DomainStateLapsed:
+		return false, ErrDomainExpired
 	}
 
-	// 如果是共享模式，允许任何人创建
-	if userDomain.Mode == domain.DomainModeShared {
+	// 如果是共享模式或白名单模式，允许任何人创建（白名单模式的前缀由 DomainWhitelistService 检查）
+	if userDomain.Mode == domain.DomainModeShared || userDomain.Mode == domain.DomainModeWhitelist {
 		return true, nil
 	}
 
-	// 如果是独享模式，只有所有者可以创建
-	if userDomain.Mode == domain.DomainModeExclusive {
-		if userID == nil || *userID != userDomain.UserID {
+	// 如果是独享模式或通配模式，只有所有者（组织域名为组织成员）可以创建
+	if userDomain.Mode == domain.DomainModeExclusive || userDomain.Mode == domain.DomainModeCatchAll {
+		if userID == nil || !s.authz.Can(ctx, *userID, userDomain.UserID, userDomain.OrgID, ActionWrite) {
 			return false, ErrDomainExclusiveMode
 		}
 		return true, nil
@@ -241,14 +333,14 @@ func (s *UserDomainService) CanCreateMailboxOnDomain(domainName string, userID *
 }
 
 // GetDomainSetupInstructions 获取域名配置说明
-func (s *UserDomainService) GetDomainSetupInstructions(domainID, userID string) (map[string]interface{}, error) {
-	userDomain, err := s.store.GetUserDomain(domainID)
+func (s *UserDomainService) GetDomainSetupInstructions(ctx context.Context, domainID, userID string) (map[string]interface{}, error) {
+	userDomain, err := s.store.GetUserDomain(ctx, domainID)
 	if err != nil {
 		return nil, ErrDomainNotFound
 	}
 
 	// 检查权限
-	if userDomain.UserID != userID {
+	if !s.authz.Can(ctx, userID, userDomain.UserID, userDomain.OrgID, ActionRead) {
 		return nil, ErrNotDomainOwner
 	}
 
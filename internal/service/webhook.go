@@ -1,25 +1,75 @@
 package service
 
 import (
+	"context"
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/security"
+	"tempmail/backend/internal/tracing"
 )
 
+var (
+	// ErrWebhookNotFound Webhook 不存在（或不属于该邮箱）
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrMailboxWebhookLimit 邮箱 Webhook 数量已达上限
+	ErrMailboxWebhookLimit = errors.New("mailbox webhook limit reached")
+	// ErrMailboxWebhookEvent 邮箱 Webhook 不支持该事件
+	ErrMailboxWebhookEvent = errors.New("event not supported for mailbox webhooks")
+	// ErrWebhookEventInvalid 订阅了不支持的事件
+	ErrWebhookEventInvalid = errors.New("unsupported webhook event")
+	// ErrWebhookDeliveryNotFound 投递记录不存在（或不属于该 Webhook）
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	// ErrWebhookDeliveryNoPayload 投递记录没有可重新投递的回调内容（生成回调内容失败）
+	ErrWebhookDeliveryNoPayload = errors.New("webhook delivery has no payload")
+)
+
+// mailboxWebhookEvents 邮箱 Webhook 可订阅的事件
+var mailboxWebhookEvents = []string{
+	string(domain.WebhookEventMailReceived),
+	string(domain.WebhookEventMailboxExpired),
+	string(domain.WebhookEventMessageDeleted),
+	string(domain.WebhookEventQuotaExceeded),
+}
+
+// GuestWebhookPolicy 邮箱 Webhook 限制来源（由 ConfigService 实现）
+type GuestWebhookPolicy interface {
+	GuestWebhooks() domain.GuestWebhookConfig
+}
+
 // WebhookService Webhook 服务
 type WebhookService struct {
 	store      domain.Store
 	httpClient *http.Client
+	backlog    atomic.Int64 // 最近一次重试扫描到的待投递数量
+	// 创建时 backfill=true 补发最近多久内收到的邮件（0 表示不补发）
+	replayWindow time.Duration
+	breaker      *WebhookBreaker // 按目标主机熔断
+	// 邮箱 Webhook：限制来自系统配置，投递只允许连接公网地址
+	guestPolicy GuestWebhookPolicy
+	guestClient *http.Client
+	validateURL func(raw string) error
+	// quota.exceeded 按配额对象限频，避免配额用尽期间每次请求都触发
+	quotaMu       sync.Mutex
+	quotaNotified map[string]time.Time
 }
 
 // NewWebhookService 创建 Webhook 服务
@@ -29,15 +79,44 @@ func NewWebhookService(store domain.Store) *WebhookService {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		breaker:     NewWebhookBreaker(DefaultBreakerConfig),
+		guestClient: security.NewPublicHTTPClient(10 * time.Second),
+		validateURL: security.ValidatePublicURL,
+	}
+}
+
+// SetGuestPolicy 设置邮箱 Webhook 限制来源（未设置时使用默认限制）
+func (s *WebhookService) SetGuestPolicy(policy GuestWebhookPolicy) {
+	s.guestPolicy = policy
+}
+
+// guestConfig 当前的邮箱 Webhook 限制
+func (s *WebhookService) guestConfig() domain.GuestWebhookConfig {
+	if s.guestPolicy == nil {
+		return domain.DefaultGuestWebhookConfig()
 	}
+	return s.guestPolicy.GuestWebhooks()
+}
+
+// Breaker 获取按目标主机的熔断器（用于设置指标、共享计数和管理查看）
+func (s *WebhookService) Breaker() *WebhookBreaker {
+	return s.breaker
 }
 
 // CreateWebhookInput 创建 Webhook 输入
 type CreateWebhookInput struct {
 	UserID      string   `json:"-"` // 从JWT中获取，不需要客户端提供
+	OrgID       *string  `json:"orgId"` // 可选：创建为组织 Webhook（需要组织成员身份）
 	URL         string   `json:"url" binding:"required,url"`
 	Events      []string `json:"events" binding:"required,min=1"`
 	Description string   `json:"description" binding:"omitempty,max=200"`
+	// 补发最近收到的邮件（mail.received，replayed=true），避免创建 Webhook 前到达的第一封邮件丢失
+	Backfill bool `json:"backfill"`
+	// 签名密钥（可选，不提供时自动生成）
+	Secret string `json:"secret" binding:"omitempty,min=16,max=255"`
+	// 回调内容格式：headers（默认）、full 或 custom（PayloadTemplate 为输出 JSON 的 Go 模板）
+	PayloadFormat   string `json:"payloadFormat"`
+	PayloadTemplate string `json:"payloadTemplate"`
 }
 
 // UpdateWebhookInput 更新 Webhook 输入
@@ -46,42 +125,163 @@ type UpdateWebhookInput struct {
 	Events      []string `json:"events" binding:"omitempty,min=1"`
 	Description string   `json:"description" binding:"omitempty,max=200"`
 	IsActive    *bool    `json:"isActive"`
+	// 修改回调内容格式或模板（只传模板时沿用当前格式）
+	PayloadFormat   string `json:"payloadFormat"`
+	PayloadTemplate string `json:"payloadTemplate"`
+	RotateSecret    bool   `json:"rotateSecret"` // 重新生成签名密钥
 }
 
 // CreateWebhook 创建 Webhook
-func (s *WebhookService) CreateWebhook(input CreateWebhookInput) (*domain.Webhook, error) {
+func (s *WebhookService) CreateWebhook(ctx context.Context, input CreateWebhookInput) (*domain.Webhook, error) {
+	if domain.InOrg(input.OrgID) {
+		if _, err := NewAuthorizer(s.store).RequireOrgRole(ctx, input.UserID, *input.OrgID, domain.OrgRoleMember); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateEvents(input.Events); err != nil {
+		return nil, err
+	}
+	format, tmpl, err := normalizePayload(input.PayloadFormat, input.PayloadTemplate)
+	if err != nil {
+		return nil, err
+	}
+
 	// 生成密钥
-	secret := generateSecret()
+	secret := input.Secret
+	if secret == "" {
+		secret = generateSecret()
+	}
 
 	webhook := &domain.Webhook{
 		ID:       uuid.New().String(),
 		UserID:   input.UserID,
+		OrgID:    input.OrgID,
 		URL:      input.URL,
 		Events:   input.Events,
 		Secret:   secret,
 		IsActive: true,
+
+		PayloadFormat:   format,
+		PayloadTemplate: tmpl,
+	}
+
+	if err := s.store.CreateWebhook(ctx, webhook); err != nil {
+		return nil, err
 	}
 
-	if err := s.store.CreateWebhook(webhook); err != nil {
+	if input.Backfill {
+		_, _ = s.Backfill(ctx, webhook)
+	}
+
+	return webhook, nil
+}
+
+// CreateMailboxWebhookInput 创建邮箱 Webhook 输入
+type CreateMailboxWebhookInput struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events"`                                    // mail.received / mailbox.expired，默认 mail.received
+	Secret string   `json:"secret" binding:"omitempty,min=16,max=255"` // 签名密钥（可选，不提供时自动生成）
+	// 回调内容格式：headers（默认）、full 或 custom
+	PayloadFormat   string `json:"payloadFormat"`
+	PayloadTemplate string `json:"payloadTemplate"`
+}
+
+// CreateMailboxWebhook 为邮箱创建 Webhook（凭邮箱令牌，无需登录）
+//
+// 回调地址必须是公网 HTTP(S) 地址；每个邮箱的数量上限和失败重试计划来自系统配置 guestWebhooks。
+func (s *WebhookService) CreateMailboxWebhook(ctx context.Context, mailboxID string, input CreateMailboxWebhookInput) (*domain.Webhook, error) {
+	if err := s.validateURL(input.URL); err != nil {
+		return nil, err
+	}
+	events := input.Events
+	if len(events) == 0 {
+		events = []string{string(domain.WebhookEventMailReceived)}
+	}
+	for _, event := range events {
+		if !containsEvent(mailboxWebhookEvents, event) {
+			return nil, ErrMailboxWebhookEvent
+		}
+	}
+	format, tmpl, err := normalizePayload(input.PayloadFormat, input.PayloadTemplate)
+	if err != nil {
 		return nil, err
 	}
 
+	existing, err := s.store.ListWebhooksByMailbox(ctx, mailboxID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= s.guestConfig().MaxPerMailbox {
+		return nil, ErrMailboxWebhookLimit
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		secret = generateSecret()
+	}
+	webhook := &domain.Webhook{
+		ID:        uuid.New().String(),
+		OwnerType: domain.WebhookOwnerMailbox,
+		OwnerID:   mailboxID,
+		URL:       input.URL,
+		Events:    events,
+		Secret:    secret,
+		IsActive:  true,
+
+		PayloadFormat:   format,
+		PayloadTemplate: tmpl,
+	}
+	if err := s.store.CreateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
 	return webhook, nil
 }
 
+// ListMailboxWebhooks 列出邮箱 Webhook
+func (s *WebhookService) ListMailboxWebhooks(ctx context.Context, mailboxID string) ([]domain.Webhook, error) {
+	return s.store.ListWebhooksByMailbox(ctx, mailboxID)
+}
+
+// DeleteMailboxWebhook 删除邮箱 Webhook（只能删除属于该邮箱的 Webhook）
+func (s *WebhookService) DeleteMailboxWebhook(ctx context.Context, mailboxID, webhookID string) error {
+	webhook, err := s.store.GetWebhook(ctx, webhookID)
+	if err != nil || !webhook.MailboxScoped() || webhook.OwnerID != mailboxID {
+		return ErrWebhookNotFound
+	}
+	return s.store.DeleteWebhook(ctx, webhookID)
+}
+
 // GetWebhook 获取 Webhook
-func (s *WebhookService) GetWebhook(id string) (*domain.Webhook, error) {
-	return s.store.GetWebhook(id)
+func (s *WebhookService) GetWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
+	return s.store.GetWebhook(ctx, id)
 }
 
-// ListWebhooks 列出用户的 Webhooks
-func (s *WebhookService) ListWebhooks(userID string) ([]domain.Webhook, error) {
-	return s.store.ListWebhooks(userID)
+// ListWebhooks 列出用户可访问的 Webhooks（个人及所在组织的 Webhook）
+func (s *WebhookService) ListWebhooks(ctx context.Context, userID string) ([]domain.Webhook, error) {
+	owned, err := s.store.ListWebhooks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.Webhook, 0, len(owned))
+	for _, webhook := range owned {
+		if !domain.InOrg(webhook.OrgID) {
+			result = append(result, webhook)
+		}
+	}
+	for _, orgID := range OrgIDsForUser(ctx, s.store, userID) {
+		orgWebhooks, err := s.store.ListWebhooksByOrgID(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, orgWebhooks...)
+	}
+	return result, nil
 }
 
 // UpdateWebhook 更新 Webhook
-func (s *WebhookService) UpdateWebhook(id string, input UpdateWebhookInput) (*domain.Webhook, error) {
-	webhook, err := s.store.GetWebhook(id)
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id string, input UpdateWebhookInput) (*domain.Webhook, error) {
+	webhook, err := s.store.GetWebhook(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -91,13 +291,30 @@ func (s *WebhookService) UpdateWebhook(id string, input UpdateWebhookInput) (*do
 		webhook.URL = input.URL
 	}
 	if len(input.Events) > 0 {
+		if err := validateEvents(input.Events); err != nil {
+			return nil, err
+		}
 		webhook.Events = input.Events
 	}
 	if input.IsActive != nil {
 		webhook.IsActive = *input.IsActive
 	}
+	if input.PayloadFormat != "" || input.PayloadTemplate != "" {
+		format := input.PayloadFormat
+		if format == "" {
+			format = webhook.PayloadFormat
+		}
+		format, tmpl, err := normalizePayload(format, input.PayloadTemplate)
+		if err != nil {
+			return nil, err
+		}
+		webhook.PayloadFormat, webhook.PayloadTemplate = format, tmpl
+	}
+	if input.RotateSecret {
+		webhook.Secret = generateSecret()
+	}
 
-	if err := s.store.UpdateWebhook(webhook); err != nil {
+	if err := s.store.UpdateWebhook(ctx, webhook); err != nil {
 		return nil, err
 	}
 
@@ -105,18 +322,188 @@ func (s *WebhookService) UpdateWebhook(id string, input UpdateWebhookInput) (*do
 }
 
 // DeleteWebhook 删除 Webhook
-func (s *WebhookService) DeleteWebhook(id string) error {
-	return s.store.DeleteWebhook(id)
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) error {
+	return s.store.DeleteWebhook(ctx, id)
+}
+
+// SetReplayWindow 设置创建 Webhook 时补发最近多久内收到的邮件
+func (s *WebhookService) SetReplayWindow(window time.Duration) {
+	s.replayWindow = window
+}
+
+// MailReceivedData mail.received 事件数据
+type MailReceivedData struct {
+	MessageID  string    `json:"messageId"`
+	MailboxID  string    `json:"mailboxId"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Subject    string    `json:"subject"`
+	ReceivedAt time.Time `json:"receivedAt"`
+	Replayed   bool      `json:"replayed,omitempty"` // 创建 Webhook 时补发的历史邮件
+	// 收信时按名单保存的头
+	Headers map[string]string `json:"headers,omitempty"`
+
+	message *domain.Message // full 和 custom 格式使用的完整邮件
+}
+
+// Backfill 为 Webhook 补发窗口内收到的邮件，返回入队的投递数
+//
+// 语义为至少一次：已有投递记录的邮件不再补发，但与实时投递并发时仍可能重复，
+// 接收方应按 messageId 去重。公开收件箱和隔离区邮件不补发。
+func (s *WebhookService) Backfill(ctx context.Context, webhook *domain.Webhook) (int, error) {
+	if s.replayWindow <= 0 || !webhook.IsActive || !containsEvent(webhook.Events, string(domain.WebhookEventMailReceived)) {
+		return 0, nil
+	}
+
+	delivered, err := s.deliveredMessageIDs(ctx, webhook.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	since := now.Add(-s.replayWindow)
+	queued := 0
+	for _, mailbox := range s.webhookMailboxes(ctx, webhook) {
+		if mailbox.IsPublic {
+			continue
+		}
+		messages, err := s.store.ListMessages(ctx, mailbox.ID)
+		if err != nil {
+			return queued, err
+		}
+		for _, message := range messages {
+			received := message.ReceivedAt
+			if received.IsZero() {
+				received = message.CreatedAt
+			}
+			if message.Quarantined || received.Before(since) || delivered[message.ID] {
+				continue
+			}
+			event := domain.WebhookEvent{
+				ID:        uuid.New().String(),
+				Event:     domain.WebhookEventMailReceived,
+				Timestamp: now,
+				Data: MailReceivedData{
+					MessageID:  message.ID,
+					MailboxID:  mailbox.ID,
+					From:       message.From,
+					To:         message.To,
+					Subject:    message.Subject,
+					ReceivedAt: received,
+					Replayed:   true,
+					Headers:    message.CapturedHeaders,
+					message:    &message,
+				},
+			}
+			go s.deliverWebhook(context.WithoutCancel(ctx), webhook, event, mailbox.ID)
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// webhookMailboxes Webhook 能收到事件的邮箱（组织 Webhook 对应组织邮箱，个人 Webhook 对应个人邮箱）
+func (s *WebhookService) webhookMailboxes(ctx context.Context, webhook *domain.Webhook) []domain.Mailbox {
+	if domain.InOrg(webhook.OrgID) {
+		return s.store.ListMailboxesByOrgID(ctx, *webhook.OrgID)
+	}
+	var personal []domain.Mailbox
+	for _, mailbox := range s.store.ListMailboxesByUserID(ctx, webhook.UserID) {
+		if !domain.InOrg(mailbox.OrgID) {
+			personal = append(personal, mailbox)
+		}
+	}
+	return personal
+}
+
+// deliveredMessageIDs 已向 Webhook 投递过 mail.received 的邮件
+func (s *WebhookService) deliveredMessageIDs(ctx context.Context, webhookID string) (map[string]bool, error) {
+	deliveries, err := s.store.GetDeliveries(ctx, webhookID, 100)
+	if err != nil {
+		return nil, err
+	}
+	delivered := make(map[string]bool, len(deliveries))
+	for _, delivery := range deliveries {
+		if delivery.Event != domain.WebhookEventMailReceived {
+			continue
+		}
+		var event struct {
+			Data struct {
+				MessageID string `json:"messageId"`
+			} `json:"data"`
+		}
+		if json.Unmarshal([]byte(delivery.Payload), &event) == nil && event.Data.MessageID != "" {
+			delivered[event.Data.MessageID] = true
+		}
+	}
+	return delivered, nil
 }
 
 // TriggerEvent 触发 Webhook 事件
-func (s *WebhookService) TriggerEvent(userID string, eventType domain.WebhookEventType, data interface{}) error {
-	// 获取用户的所有 Webhooks
-	webhooks, err := s.store.ListWebhooks(userID)
+func (s *WebhookService) TriggerEvent(ctx context.Context, userID string, eventType domain.WebhookEventType, data interface{}) error {
+	return s.TriggerMailboxEvent(ctx, userID, "", eventType, data)
+}
+
+// TriggerMailboxEvent 触发与邮箱关联的 Webhook 事件，邮箱删除时其待重试投递会被取消
+func (s *WebhookService) TriggerMailboxEvent(ctx context.Context, userID, mailboxID string, eventType domain.WebhookEventType, data interface{}) error {
+	var mailbox *domain.Mailbox
+	if mailboxID != "" {
+		if found, err := s.store.GetMailbox(ctx, mailboxID); err == nil {
+			mailbox = found
+		}
+	}
+	return s.trigger(ctx, userID, mailboxID, mailbox, eventType, data)
+}
+
+// trigger 向订阅了事件的 Webhooks 异步投递；mailbox 为 nil 表示邮箱已不存在或与邮箱无关
+func (s *WebhookService) trigger(ctx context.Context, userID, mailboxID string, mailbox *domain.Mailbox, eventType domain.WebhookEventType, data interface{}) error {
+	// 获取用户的个人 Webhooks；组织邮箱的事件同时发给组织 Webhooks，邮箱事件同时发给该邮箱的 Webhooks
+	webhooks, err := s.personalWebhooks(ctx, userID)
 	if err != nil {
 		return err
 	}
+	if mailboxID != "" {
+		if mailbox != nil && mailbox.IsPublic {
+			return nil // 公开收件箱不触发 Webhook
+		}
+		if mailbox != nil && domain.InOrg(mailbox.OrgID) {
+			orgWebhooks, err := s.store.ListWebhooksByOrgID(ctx, *mailbox.OrgID)
+			if err != nil {
+				return err
+			}
+			webhooks = append(webhooks, orgWebhooks...)
+		}
+		mailboxWebhooks, err := s.store.ListWebhooksByMailbox(ctx, mailboxID)
+		if err != nil {
+			return err
+		}
+		webhooks = append(webhooks, mailboxWebhooks...)
+	}
+
+	s.dispatch(ctx, webhooks, mailboxID, eventType, data)
+	return nil
+}
 
+// personalWebhooks 用户的个人 Webhooks（不含组织和邮箱 Webhooks）
+func (s *WebhookService) personalWebhooks(ctx context.Context, userID string) ([]domain.Webhook, error) {
+	if userID == "" {
+		return nil, nil
+	}
+	owned, err := s.store.ListWebhooks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var webhooks []domain.Webhook
+	for _, webhook := range owned {
+		if !domain.InOrg(webhook.OrgID) && !webhook.MailboxScoped() {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+// dispatch 向订阅了事件的已启用 Webhooks 异步投递
+func (s *WebhookService) dispatch(ctx context.Context, webhooks []domain.Webhook, mailboxID string, eventType domain.WebhookEventType, data interface{}) {
 	// 构建事件数据
 	event := domain.WebhookEvent{
 		ID:        uuid.New().String(),
@@ -138,33 +525,117 @@ func (s *WebhookService) TriggerEvent(userID string, eventType domain.WebhookEve
 		}
 
 		// 异步发送
-		go s.deliverWebhook(&webhook, event)
+		go s.deliverWebhook(context.WithoutCancel(ctx), &webhook, event, mailboxID)
 	}
+}
 
-	return nil
+// NotifyMailReceived 新邮件入库后触发 mail.received（隔离区邮件不触发）
+func (s *WebhookService) NotifyMailReceived(message *domain.Message) {
+	if message.Quarantined {
+		return
+	}
+	// 邮件已经入库，事件不随触发它的请求或 SMTP 会话取消
+	ctx := context.Background()
+	mailbox, err := s.store.GetMailbox(ctx, message.MailboxID)
+	if err != nil {
+		return
+	}
+	received := message.ReceivedAt
+	if received.IsZero() {
+		received = message.CreatedAt
+	}
+	_ = s.trigger(ctx, mailboxOwner(mailbox), mailbox.ID, mailbox, domain.WebhookEventMailReceived, MailReceivedData{
+		MessageID:  message.ID,
+		MailboxID:  mailbox.ID,
+		From:       message.From,
+		To:         message.To,
+		Subject:    message.Subject,
+		ReceivedAt: received,
+		Headers:    message.CapturedHeaders,
+		message:    message,
+	})
 }
 
-// deliverWebhook 投递 Webhook
-func (s *WebhookService) deliverWebhook(webhook *domain.Webhook, event domain.WebhookEvent) {
-	delivery := &domain.WebhookDelivery{
-		ID:        uuid.New().String(),
-		WebhookID: webhook.ID,
-		Event:     event.Event,
-		Attempts:  1,
+// MailboxExpiredData mailbox.expired 事件数据
+type MailboxExpiredData struct {
+	MailboxID string     `json:"mailboxId"`
+	Address   string     `json:"address"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// NotifyMailboxExpired 过期邮箱删除前触发 mailbox.expired
+//
+// 使用调用方传入的邮箱（不再查询，已过期的邮箱可能查不到）；邮箱随即被删除，
+// 待重试的投递会被取消，因此该事件只投递一次。
+func (s *WebhookService) NotifyMailboxExpired(mailbox *domain.Mailbox) {
+	_ = s.trigger(context.Background(), mailboxOwner(mailbox), mailbox.ID, mailbox, domain.WebhookEventMailboxExpired, MailboxExpiredData{
+		MailboxID: mailbox.ID,
+		Address:   mailbox.Address,
+		ExpiresAt: mailbox.ExpiresAt,
+	})
+}
+
+// mailboxOwner 邮箱所属用户ID（游客邮箱为空）
+func mailboxOwner(mailbox *domain.Mailbox) string {
+	if mailbox.UserID == nil {
+		return ""
 	}
+	return *mailbox.UserID
+}
 
-	// 序列化 payload
-	payload, err := json.Marshal(event)
+// deliverWebhook 投递 Webhook
+func (s *WebhookService) deliverWebhook(ctx context.Context, webhook *domain.Webhook, event domain.WebhookEvent, mailboxID string) {
+	s.deliverAttempt(ctx, webhook, event, mailboxID, 1)
+}
+
+// deliverAttempt 第 attempts 次投递 Webhook：按内容格式生成回调内容后发送
+func (s *WebhookService) deliverAttempt(ctx context.Context, webhook *domain.Webhook, event domain.WebhookEvent, mailboxID string, attempts int) {
+	payload, err := renderPayload(webhook, event)
 	if err != nil {
-		delivery.Success = false
-		delivery.Error = fmt.Sprintf("failed to marshal payload: %v", err)
-		s.store.RecordDelivery(delivery)
+		s.store.RecordDelivery(ctx, &domain.WebhookDelivery{
+			ID:        uuid.New().String(),
+			WebhookID: webhook.ID,
+			MailboxID: mailboxID,
+			Event:     event.Event,
+			Attempts:  attempts,
+			Error:     fmt.Sprintf("failed to render payload: %v", err),
+		})
 		return
 	}
-	delivery.Payload = string(payload)
+	s.send(ctx, webhook, event.Event, payload, mailboxID, attempts, false)
+}
 
-	// 生成签名
-	signature := generateSignature(payload, webhook.Secret)
+// send 发送回调内容并记录投递结果（目标主机熔断时不发起连接，直接按退避排队重试）
+//
+// 失败且不再重试的投递标记为死信，等待人工重新投递。test 为 true 时是测试投递：不经过熔断器，失败不重试，也不进入死信。
+func (s *WebhookService) send(ctx context.Context, webhook *domain.Webhook, eventType domain.WebhookEventType, payload []byte, mailboxID string, attempts int, test bool) *domain.WebhookDelivery {
+	delivery := &domain.WebhookDelivery{
+		ID:        uuid.New().String(),
+		WebhookID: webhook.ID,
+		MailboxID: mailboxID,
+		Event:     eventType,
+		Attempts:  attempts,
+		Payload:   string(payload),
+	}
+	retry := func() *time.Time {
+		if test {
+			return nil
+		}
+		return s.nextRetry(webhook, delivery.Attempts)
+	}
+	ctx, span := tracing.Start(ctx, "webhook.deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("webhook.id", webhook.ID),
+			attribute.String("webhook.event", string(eventType)),
+			attribute.Int("webhook.attempt", attempts),
+		),
+	)
+	defer endDeliverySpan(span, delivery)
+	record := func() {
+		delivery.DeadLetter = !test && !delivery.Success && delivery.NextRetry == nil
+		s.store.RecordDelivery(ctx, delivery)
+	}
 
 	// 发送 HTTP 请求
 	startTime := time.Now()
@@ -173,27 +644,56 @@ func (s *WebhookService) deliverWebhook(webhook *domain.Webhook, event domain.We
 		delivery.Success = false
 		delivery.Error = fmt.Sprintf("failed to create request: %v", err)
 		delivery.Duration = time.Since(startTime).Milliseconds()
-		s.store.RecordDelivery(delivery)
-		return
+		record()
+		return delivery
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Webhook-Signature", signature)
-	req.Header.Set("X-Webhook-Event", string(event.Event))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header)) // 接收方可把处理过程接到同一条链路上
+	req.Header.Set("X-Tempmail-Signature", SignWebhookPayload(webhook.Secret, startTime.Unix(), payload))
+	req.Header.Set("X-Webhook-Signature", generateSignature(payload, webhook.Secret)) // 旧版签名（不含时间戳），保留兼容
+	req.Header.Set("X-Webhook-Event", string(eventType))
 	req.Header.Set("X-Webhook-ID", delivery.ID)
+	if test {
+		req.Header.Set("X-Webhook-Test", "true")
+	}
+
+	// 熔断中：不连接目标主机，短路的尝试同样计入退避次数
+	host := breakerHost(webhook.URL)
+	if !test && !s.breaker.Allow(ctx, host) {
+		delivery.Success = false
+		delivery.Error = fmt.Sprintf("circuit open for %s", host)
+		delivery.ErrorKind = domain.WebhookErrorKindCircuitOpen
+		delivery.NextRetry = retry()
+		record()
+		return delivery
+	}
 
-	resp, err := s.httpClient.Do(req)
+	client := s.httpClient
+	if webhook.MailboxScoped() {
+		client = s.guestClient
+	}
+	resp, err := client.Do(req)
 	delivery.Duration = time.Since(startTime).Milliseconds()
 
 	if err != nil {
+		if !test {
+			s.breaker.Record(ctx, host, true)
+		}
 		delivery.Success = false
 		delivery.Error = fmt.Sprintf("failed to send request: %v", err)
-		delivery.NextRetry = calculateNextRetry(delivery.Attempts)
-		s.store.RecordDelivery(delivery)
-		return
+		delivery.ErrorKind = domain.WebhookErrorKindNetwork
+		delivery.NextRetry = retry()
+		record()
+		return delivery
 	}
 	defer resp.Body.Close()
 
+	// 5xx 视为接收方故障；4xx 说明主机可用，不触发熔断
+	if !test {
+		s.breaker.Record(ctx, host, resp.StatusCode >= 500)
+	}
+
 	delivery.StatusCode = resp.StatusCode
 
 	// 读取响应
@@ -206,50 +706,121 @@ func (s *WebhookService) deliverWebhook(webhook *domain.Webhook, event domain.We
 	} else {
 		delivery.Success = false
 		delivery.Error = fmt.Sprintf("HTTP %d: %s", resp.StatusCode, delivery.Response)
+		delivery.ErrorKind = domain.WebhookErrorKindHTTPStatus
 		
 		// 如果失败，计算下次重试时间
 		if delivery.Attempts < 5 {
-			delivery.NextRetry = calculateNextRetry(delivery.Attempts)
+			delivery.NextRetry = retry()
 		}
 	}
 
-	s.store.RecordDelivery(delivery)
+	record()
+	return delivery
+}
+
+// endDeliverySpan 在投递 span 上记录结果后结束 span
+func endDeliverySpan(span trace.Span, delivery *domain.WebhookDelivery) {
+	if delivery.StatusCode != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", delivery.StatusCode))
+	}
+	if !delivery.Success {
+		span.SetStatus(codes.Error, delivery.Error)
+	}
+	span.End()
+}
+
+// TestWebhook 同步发送一次示例事件（data 为示例数据，test=true），返回投递结果
+//
+// eventType 为空时使用 Webhook 订阅的第一个事件；测试投递不经过熔断器，失败不重试。
+func (s *WebhookService) TestWebhook(ctx context.Context, webhook *domain.Webhook, eventType string) (*domain.WebhookDelivery, error) {
+	if eventType == "" && len(webhook.Events) > 0 {
+		eventType = webhook.Events[0]
+	}
+	if eventType == "" || !domain.ValidWebhookEvent(eventType) {
+		return nil, ErrWebhookTestEvent
+	}
+	event := sampleEvent(domain.WebhookEventType(eventType))
+	event.ID = uuid.New().String()
+	payload, err := renderPayload(webhook, event)
+	if err != nil {
+		return nil, err
+	}
+	return s.send(ctx, webhook, event.Event, payload, "", 1, true), nil
 }
 
 // GetDeliveries 获取投递记录
-func (s *WebhookService) GetDeliveries(webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+func (s *WebhookService) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 	if limit > 100 {
 		limit = 100
 	}
-	return s.store.GetDeliveries(webhookID, limit)
+	return s.store.GetDeliveries(ctx, webhookID, limit)
+}
+
+// ListDeadLetters 列出尚未重新投递的死信（webhookID 为空时列出全部 Webhook 的死信）
+func (s *WebhookService) ListDeadLetters(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return s.store.ListDeadLetters(ctx, webhookID, limit)
+}
+
+// Redeliver 同步重新发送投递记录中的回调内容，返回新的投递记录
+//
+// 使用当前的签名密钥和回调地址；新投递按正常计划重试，原记录标记为已重新投递，不再列入死信。
+func (s *WebhookService) Redeliver(ctx context.Context, webhook *domain.Webhook, deliveryID string) (*domain.WebhookDelivery, error) {
+	delivery, err := s.store.GetDelivery(ctx, deliveryID)
+	if err != nil || delivery.WebhookID != webhook.ID {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	if delivery.Payload == "" {
+		return nil, ErrWebhookDeliveryNoPayload
+	}
+
+	redelivered := s.send(ctx, webhook, delivery.Event, []byte(delivery.Payload), delivery.MailboxID, 1, false)
+
+	now := time.Now()
+	delivery.RedeliveredAt = &now
+	delivery.NextRetry = nil
+	if err := s.store.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return redelivered, nil
+}
+
+// Backlog 获取待重试投递积压数量（最近一次扫描结果）
+func (s *WebhookService) Backlog() int {
+	return int(s.backlog.Load())
 }
 
 // RetryFailedDeliveries 重试失败的投递
-func (s *WebhookService) RetryFailedDeliveries() error {
+func (s *WebhookService) RetryFailedDeliveries(ctx context.Context) error {
 	// 获取待重试的投递
-	deliveries, err := s.store.GetPendingDeliveries(10)
+	deliveries, err := s.store.GetPendingDeliveries(ctx, 10)
 	if err != nil {
 		return err
 	}
+	s.backlog.Store(int64(len(deliveries)))
 
 	// 重新投递
 	for _, delivery := range deliveries {
-		webhook, err := s.store.GetWebhook(delivery.WebhookID)
+		webhook, err := s.store.GetWebhook(ctx, delivery.WebhookID)
 		if err != nil {
 			continue
 		}
 
-		// 解析事件数据
-		var event domain.WebhookEvent
-		if err := json.Unmarshal([]byte(delivery.Payload), &event); err != nil {
-			continue
-		}
+		// 已取出的记录不再待重试（重试结果另记一条）
+		retried := delivery
+		retried.NextRetry = nil
+		_ = s.store.UpdateDelivery(ctx, &retried)
 
-		// 异步重试
-		go s.deliverWebhook(webhook, event)
+		// 异步重试原样发送已生成的回调内容（尝试次数累加，决定下次退避间隔）
+		go s.send(context.WithoutCancel(ctx), webhook, delivery.Event, []byte(delivery.Payload), delivery.MailboxID, delivery.Attempts+1, false)
 	}
 
 	return nil
@@ -267,26 +838,50 @@ func generateSignature(payload []byte, secret string) string {
 	return "sha256=" + hex.EncodeToString(h.Sum(nil))
 }
 
-// calculateNextRetry 计算下次重试时间（指数退避）
-func calculateNextRetry(attempts int) *time.Time {
-	// 重试间隔：1分钟、5分钟、15分钟、1小时、6小时
-	intervals := []time.Duration{
-		1 * time.Minute,
-		5 * time.Minute,
-		15 * time.Minute,
-		1 * time.Hour,
-		6 * time.Hour,
+// nextRetry 计算下次重试时间：邮箱 Webhook 使用系统配置中更短的重试计划
+func (s *WebhookService) nextRetry(webhook *domain.Webhook, attempts int) *time.Time {
+	if !webhook.MailboxScoped() {
+		return calculateNextRetry(attempts)
 	}
-
+	intervals := s.guestConfig().RetryIntervals()
 	index := attempts - 1
 	if index >= len(intervals) {
 		return nil // 不再重试
 	}
-
 	nextRetry := time.Now().Add(intervals[index])
 	return &nextRetry
 }
 
+// retryIntervals 投递失败后的重试间隔：1分钟、5分钟、15分钟、1小时、6小时（邮件转发共用）
+var retryIntervals = []time.Duration{
+	1 * time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	1 * time.Hour,
+	6 * time.Hour,
+}
+
+// calculateNextRetry 计算下次重试时间（指数退避）
+func calculateNextRetry(attempts int) *time.Time {
+	index := attempts - 1
+	if index >= len(retryIntervals) {
+		return nil // 不再重试
+	}
+
+	nextRetry := time.Now().Add(retryIntervals[index])
+	return &nextRetry
+}
+
+// validateEvents 检查订阅的事件是否都受支持
+func validateEvents(events []string) error {
+	for _, event := range events {
+		if !domain.ValidWebhookEvent(event) {
+			return ErrWebhookEventInvalid
+		}
+	}
+	return nil
+}
+
 // containsEvent 检查事件列表是否包含指定事件
 func containsEvent(events []string, event string) bool {
 	for _, e := range events {
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Webhook 熔断状态
+const (
+	BreakerStateClosed   = "closed"
+	BreakerStateOpen     = "open"
+	BreakerStateHalfOpen = "half_open"
+)
+
+// BreakerConfig 熔断参数（按目标主机统计）
+type BreakerConfig struct {
+	FailureThreshold int           // 连续失败多少次后熔断
+	FailureRate      float64       // 最近窗口内失败率达到该值后熔断
+	MinSamples       int           // 失败率判定所需的最少样本数
+	WindowSize       int           // 失败率统计窗口（最近 N 次投递）
+	Cooldown         time.Duration // 熔断后多久进入半开状态探测
+}
+
+// DefaultBreakerConfig 默认熔断参数
+var DefaultBreakerConfig = BreakerConfig{
+	FailureThreshold: 5,
+	FailureRate:      0.5,
+	MinSamples:       10,
+	WindowSize:       20,
+	Cooldown:         time.Minute,
+}
+
+// BreakerMetrics 熔断指标
+type BreakerMetrics interface {
+	RecordWebhookBreakerState(host, state string)
+	RecordWebhookShortCircuit(host string)
+}
+
+// BreakerCounters 跨实例共享的连续失败计数（hybrid 模式下由 Redis 提供）
+type BreakerCounters interface {
+	IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error)
+	GetRateLimit(ctx context.Context, key string) (int64, error)
+	ResetRateLimit(ctx context.Context, key string) error
+}
+
+// BreakerStatus 熔断中的主机状态
+type BreakerStatus struct {
+	Host                string    `json:"host"`
+	State               string    `json:"state"`
+	Since               time.Time `json:"since"`               // 熔断开始时间
+	NextProbeAt         time.Time `json:"nextProbeAt"`         // 下次探测时间（半开状态下为探测开始时间）
+	ConsecutiveFailures int       `json:"consecutiveFailures"` // 连续失败次数
+	ShortCircuited      int64     `json:"shortCircuited"`      // 本次熔断期间被短路的投递数
+}
+
+// hostBreaker 单个主机的熔断状态
+type hostBreaker struct {
+	state          string
+	consecutive    int
+	outcomes       []bool // 最近投递结果（true 为失败），环形缓冲
+	next           int
+	openedAt       time.Time
+	probing        bool
+	shortCircuited int64
+}
+
+// WebhookBreaker 按目标主机（scheme+host+port）熔断 Webhook 投递，
+// 避免一个挂掉的共享接收方被所有 Webhook 反复连接
+type WebhookBreaker struct {
+	mu       sync.Mutex
+	cfg      BreakerConfig
+	hosts    map[string]*hostBreaker
+	metrics  BreakerMetrics
+	counters BreakerCounters
+	now      func() time.Time
+}
+
+// NewWebhookBreaker 创建熔断器
+func NewWebhookBreaker(cfg BreakerConfig) *WebhookBreaker {
+	return &WebhookBreaker{
+		cfg:   cfg,
+		hosts: make(map[string]*hostBreaker),
+		now:   time.Now,
+	}
+}
+
+// SetMetrics 设置熔断指标
+func (b *WebhookBreaker) SetMetrics(metrics BreakerMetrics) {
+	b.metrics = metrics
+}
+
+// SetCounters 设置共享失败计数，多实例部署时各实例据此更快地一致熔断
+func (b *WebhookBreaker) SetCounters(counters BreakerCounters) {
+	b.counters = counters
+}
+
+// Allow 判断是否可以向主机发起投递；半开状态只放行一次探测
+func (b *WebhookBreaker) Allow(ctx context.Context, host string) bool {
+	shared := b.sharedFailures(ctx, host)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hb := b.host(host)
+	now := b.now()
+	switch hb.state {
+	case BreakerStateClosed:
+		if shared >= b.cfg.FailureThreshold {
+			b.transition(host, hb, BreakerStateOpen)
+			hb.openedAt = now
+			return b.shortCircuit(host, hb)
+		}
+		return true
+	case BreakerStateOpen:
+		if now.Before(hb.openedAt.Add(b.cfg.Cooldown)) {
+			return b.shortCircuit(host, hb)
+		}
+		b.transition(host, hb, BreakerStateHalfOpen)
+		hb.probing = true
+		return true
+	default: // 半开：已有探测在进行
+		if hb.probing {
+			return b.shortCircuit(host, hb)
+		}
+		hb.probing = true
+		return true
+	}
+}
+
+// Record 记录一次实际投递的结果
+func (b *WebhookBreaker) Record(ctx context.Context, host string, failed bool) {
+	b.recordShared(ctx, host, failed)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hb := b.host(host)
+	if len(hb.outcomes) < b.cfg.WindowSize {
+		hb.outcomes = append(hb.outcomes, failed)
+	} else if b.cfg.WindowSize > 0 {
+		hb.outcomes[hb.next] = failed
+		hb.next = (hb.next + 1) % b.cfg.WindowSize
+	}
+
+	if !failed {
+		hb.consecutive = 0
+		if hb.state != BreakerStateClosed {
+			b.transition(host, hb, BreakerStateClosed)
+			hb.outcomes, hb.next = nil, 0
+			hb.probing = false
+			hb.shortCircuited = 0
+		}
+		return
+	}
+
+	hb.consecutive++
+	switch hb.state {
+	case BreakerStateHalfOpen:
+		// 探测失败，重新熔断
+		b.transition(host, hb, BreakerStateOpen)
+		hb.openedAt = b.now()
+		hb.probing = false
+	case BreakerStateClosed:
+		if hb.consecutive >= b.cfg.FailureThreshold || b.failureRateExceeded(hb) {
+			b.transition(host, hb, BreakerStateOpen)
+			hb.openedAt = b.now()
+		}
+	}
+}
+
+// List 列出熔断中（open / half_open）的主机
+func (b *WebhookBreaker) List() []BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make([]BreakerStatus, 0)
+	for host, hb := range b.hosts {
+		if hb.state == BreakerStateClosed {
+			continue
+		}
+		result = append(result, BreakerStatus{
+			Host:                host,
+			State:               hb.state,
+			Since:               hb.openedAt,
+			NextProbeAt:         hb.openedAt.Add(b.cfg.Cooldown),
+			ConsecutiveFailures: hb.consecutive,
+			ShortCircuited:      hb.shortCircuited,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Host < result[j].Host })
+	return result
+}
+
+// host 获取主机状态（调用方持有锁）
+func (b *WebhookBreaker) host(host string) *hostBreaker {
+	hb := b.hosts[host]
+	if hb == nil {
+		hb = &hostBreaker{state: BreakerStateClosed}
+		b.hosts[host] = hb
+	}
+	return hb
+}
+
+// transition 切换状态并更新指标（调用方持有锁）
+func (b *WebhookBreaker) transition(host string, hb *hostBreaker, state string) {
+	hb.state = state
+	if b.metrics != nil {
+		b.metrics.RecordWebhookBreakerState(host, state)
+	}
+}
+
+// shortCircuit 记录一次被短路的投递（调用方持有锁）
+func (b *WebhookBreaker) shortCircuit(host string, hb *hostBreaker) bool {
+	hb.shortCircuited++
+	if b.metrics != nil {
+		b.metrics.RecordWebhookShortCircuit(host)
+	}
+	return false
+}
+
+// failureRateExceeded 最近窗口内失败率是否超限
+func (b *WebhookBreaker) failureRateExceeded(hb *hostBreaker) bool {
+	if b.cfg.FailureRate <= 0 || len(hb.outcomes) < b.cfg.MinSamples {
+		return false
+	}
+	failures := 0
+	for _, failed := range hb.outcomes {
+		if failed {
+			failures++
+		}
+	}
+	return float64(failures)/float64(len(hb.outcomes)) >= b.cfg.FailureRate
+}
+
+// sharedFailures 读取共享的连续失败次数（未配置或读取失败时为 0）
+func (b *WebhookBreaker) sharedFailures(ctx context.Context, host string) int {
+	if b.counters == nil {
+		return 0
+	}
+	count, err := b.counters.GetRateLimit(ctx, breakerCounterKey(host))
+	if err != nil {
+		return 0
+	}
+	return int(count)
+}
+
+// recordShared 更新共享的连续失败次数，成功时清零
+func (b *WebhookBreaker) recordShared(ctx context.Context, host string, failed bool) {
+	if b.counters == nil {
+		return
+	}
+	key := breakerCounterKey(host)
+	if failed {
+		_, _ = b.counters.IncrementRateLimit(ctx, key, b.cfg.Cooldown)
+		return
+	}
+	_ = b.counters.ResetRateLimit(ctx, key)
+}
+
+func breakerCounterKey(host string) string {
+	return "webhook_breaker:" + host
+}
+
+// breakerHost 规范化 Webhook 地址的目标主机（scheme://host:port，缺省端口补全）
+func breakerHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	scheme := strings.ToLower(u.Scheme)
+	port := u.Port()
+	if port == "" {
+		switch scheme {
+		case "https":
+			port = "443"
+		default:
+			port = "80"
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
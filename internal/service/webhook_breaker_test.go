@@ -0,0 +1,194 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// countingTransport 统计实际发出的请求；down=true 时模拟连接失败
+type countingTransport struct {
+	dials atomic.Int32
+	down  atomic.Bool
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.dials.Add(1)
+	if t.down.Load() {
+		return nil, errors.New("dial tcp: connection refused")
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Request: req}, nil
+}
+
+func TestWebhookBreaker(t *testing.T) {
+	cfg := BreakerConfig{FailureThreshold: 3, FailureRate: 0.5, MinSamples: 10, WindowSize: 20, Cooldown: time.Minute}
+
+	setup := func(t *testing.T) (*memory.Store, *WebhookService, *countingTransport, *time.Time, []*domain.Webhook) {
+		t.Helper()
+		store := memory.NewStore(24 * time.Hour)
+		transport := &countingTransport{}
+		transport.down.Store(true)
+
+		webhooks := NewWebhookService(store)
+		webhooks.httpClient = &http.Client{Transport: transport}
+		webhooks.breaker = NewWebhookBreaker(cfg)
+		now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+		webhooks.breaker.now = func() time.Time { return now }
+
+		// 三个 Webhook 共用同一个接收方主机
+		var hooks []*domain.Webhook
+		for _, path := range []string{"/a", "/b", "/c"} {
+			hook := &domain.Webhook{ID: "wh" + path, UserID: "user-1", URL: "https://Hooks.Example.com" + path, Events: []string{"mail.received"}, IsActive: true}
+			require.NoError(t, store.CreateWebhook(t.Context(), hook))
+			hooks = append(hooks, hook)
+		}
+		return store, webhooks, transport, &now, hooks
+	}
+	event := domain.WebhookEvent{ID: "evt-1", Event: domain.WebhookEventMailReceived, Timestamp: time.Now()}
+
+	t.Run("共享主机故障时只有前几次投递发起连接", func(t *testing.T) {
+		store, webhooks, transport, _, hooks := setup(t)
+		for round := 0; round < 3; round++ {
+			for _, hook := range hooks {
+				webhooks.deliverWebhook(t.Context(), hook, event, "")
+			}
+		}
+		assert.Equal(t, int32(3), transport.dials.Load())
+
+		shortCircuited, retryCount := 0, 0
+		for _, hook := range hooks {
+			deliveries, err := store.GetDeliveries(t.Context(), hook.ID, 10)
+			require.NoError(t, err)
+			for _, delivery := range deliveries {
+				require.NotNil(t, delivery.NextRetry, "短路的投递立即排队重试")
+				if delivery.ErrorKind == domain.WebhookErrorKindCircuitOpen {
+					shortCircuited++
+				} else {
+					assert.Equal(t, domain.WebhookErrorKindNetwork, delivery.ErrorKind)
+				}
+			}
+			stored, err := store.GetWebhook(t.Context(), hook.ID)
+			require.NoError(t, err)
+			retryCount += stored.RetryCount
+		}
+		assert.Equal(t, 6, shortCircuited)
+		assert.Equal(t, 3, retryCount, "短路不计入 Webhook 失败次数")
+	})
+
+	t.Run("短路的尝试计入退避次数", func(t *testing.T) {
+		store, webhooks, _, _, hooks := setup(t)
+		for i := 0; i < cfg.FailureThreshold; i++ {
+			webhooks.deliverWebhook(t.Context(), hooks[0], event, "")
+		}
+		webhooks.deliverAttempt(t.Context(), hooks[1], event, "", 3)
+
+		deliveries, err := store.GetDeliveries(t.Context(), hooks[1].ID, 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, domain.WebhookErrorKindCircuitOpen, deliveries[0].ErrorKind)
+		assert.Equal(t, 3, deliveries[0].Attempts)
+		require.NotNil(t, deliveries[0].NextRetry)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), *deliveries[0].NextRetry, time.Minute)
+	})
+
+	t.Run("冷却后半开探测，成功则恢复", func(t *testing.T) {
+		_, webhooks, transport, now, hooks := setup(t)
+		for i := 0; i < cfg.FailureThreshold; i++ {
+			webhooks.deliverWebhook(t.Context(), hooks[0], event, "")
+		}
+		host := breakerHost(hooks[0].URL)
+
+		// 冷却期内不探测
+		*now = now.Add(30 * time.Second)
+		assert.False(t, webhooks.breaker.Allow(t.Context(), host))
+
+		// 冷却结束：只放行一次探测
+		*now = now.Add(31 * time.Second)
+		assert.True(t, webhooks.breaker.Allow(t.Context(), host))
+		assert.False(t, webhooks.breaker.Allow(t.Context(), host))
+
+		// 探测失败重新熔断，再次冷却后探测成功则关闭
+		webhooks.breaker.Record(t.Context(), host, true)
+		assert.False(t, webhooks.breaker.Allow(t.Context(), host))
+		*now = now.Add(cfg.Cooldown)
+		transport.down.Store(false)
+		dials := transport.dials.Load()
+		webhooks.deliverWebhook(t.Context(), hooks[2], event, "")
+		assert.Equal(t, dials+1, transport.dials.Load())
+		assert.Empty(t, webhooks.breaker.List())
+
+		webhooks.deliverWebhook(t.Context(), hooks[1], event, "")
+		assert.Equal(t, dials+2, transport.dials.Load())
+	})
+
+	t.Run("管理列表反映状态变化", func(t *testing.T) {
+		_, webhooks, _, now, hooks := setup(t)
+		breaker := webhooks.breaker
+		assert.Empty(t, breaker.List())
+
+		opened := *now
+		for i := 0; i < cfg.FailureThreshold; i++ {
+			webhooks.deliverWebhook(t.Context(), hooks[0], event, "")
+		}
+		webhooks.deliverWebhook(t.Context(), hooks[1], event, "")
+
+		list := breaker.List()
+		require.Len(t, list, 1)
+		assert.Equal(t, "https://hooks.example.com:443", list[0].Host)
+		assert.Equal(t, BreakerStateOpen, list[0].State)
+		assert.Equal(t, opened, list[0].Since)
+		assert.Equal(t, opened.Add(cfg.Cooldown), list[0].NextProbeAt)
+		assert.Equal(t, 3, list[0].ConsecutiveFailures)
+		assert.Equal(t, int64(1), list[0].ShortCircuited)
+
+		*now = now.Add(cfg.Cooldown)
+		require.True(t, breaker.Allow(t.Context(), list[0].Host))
+		list = breaker.List()
+		require.Len(t, list, 1)
+		assert.Equal(t, BreakerStateHalfOpen, list[0].State)
+
+		breaker.Record(t.Context(), list[0].Host, false)
+		assert.Empty(t, breaker.List())
+	})
+
+	t.Run("失败率超限时熔断", func(t *testing.T) {
+		breaker := NewWebhookBreaker(cfg)
+		host := "https://flaky.example.com:443"
+		for i := 0; i < 10; i++ {
+			require.True(t, breaker.Allow(t.Context(), host))
+			breaker.Record(t.Context(), host, i%2 == 1)
+		}
+		assert.False(t, breaker.Allow(t.Context(), host))
+	})
+
+	t.Run("共享计数让其他实例一并熔断", func(t *testing.T) {
+		counters := memory.NewStore(time.Hour)
+		first, second := NewWebhookBreaker(cfg), NewWebhookBreaker(cfg)
+		first.SetCounters(counters)
+		second.SetCounters(counters)
+		host := "https://shared.example.com:443"
+
+		for i := 0; i < cfg.FailureThreshold; i++ {
+			first.Record(t.Context(), host, true)
+		}
+		assert.False(t, second.Allow(t.Context(), host))
+		require.Len(t, second.List(), 1)
+	})
+}
+
+func TestBreakerHost(t *testing.T) {
+	assert.Equal(t, "https://hooks.example.com:443", breakerHost("https://Hooks.Example.com/a?x=1"))
+	assert.Equal(t, "http://hooks.example.com:80", breakerHost("http://hooks.example.com/b"))
+	assert.Equal(t, "http://127.0.0.1:8080", breakerHost("http://127.0.0.1:8080/c"))
+	assert.Equal(t, "https://[::1]:8443", breakerHost("https://[::1]:8443/"))
+}
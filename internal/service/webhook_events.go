@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// QuotaEventInterval 同一配额对象两次 quota.exceeded 事件的最小间隔
+const QuotaEventInterval = time.Hour
+
+// quota.exceeded 事件的配额范围
+const (
+	QuotaScopeOrgMailboxes    = "org.mailboxes"    // 组织邮箱数量
+	QuotaScopeUserMailboxes   = "user.mailboxes"   // 用户个人邮箱数量
+	QuotaScopeMailboxMessages = "mailbox.messages" // 邮箱邮件数量
+	QuotaScopeMailboxSend     = "send.mailbox"     // 邮箱发信配额
+	QuotaScopeUserSend        = "send.user"        // 用户发信配额
+)
+
+// QuotaExceededNotifier 配额用尽通知（由 WebhookService 实现，触发 quota.exceeded）
+type QuotaExceededNotifier interface {
+	NotifyQuotaExceeded(data QuotaExceededData)
+}
+
+// MailboxCreatedNotifiers 把邮箱创建通知依次转发给多个接收方
+type MailboxCreatedNotifiers []MailboxCreatedNotifier
+
+// NotifyMailboxCreated 实现 MailboxCreatedNotifier
+func (n MailboxCreatedNotifiers) NotifyMailboxCreated(mailbox *domain.Mailbox) {
+	for _, notifier := range n {
+		notifier.NotifyMailboxCreated(mailbox)
+	}
+}
+
+// MailboxCreatedData mailbox.created 事件数据
+type MailboxCreatedData struct {
+	MailboxID string     `json:"mailboxId"`
+	Address   string     `json:"address"`
+	OrgID     string     `json:"orgId,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// MessageDeletedData message.deleted 事件数据（清空邮箱时 messageId 为空、cleared 为 true）
+type MessageDeletedData struct {
+	MailboxID string `json:"mailboxId"`
+	MessageID string `json:"messageId,omitempty"`
+	Count     int    `json:"count"`
+	Cleared   bool   `json:"cleared,omitempty"`
+}
+
+// DomainVerifiedData domain.verified 事件数据
+type DomainVerifiedData struct {
+	DomainID   string     `json:"domainId"`
+	Domain     string     `json:"domain"`
+	OrgID      string     `json:"orgId,omitempty"`
+	VerifiedAt *time.Time `json:"verifiedAt,omitempty"`
+}
+
+// QuotaExceededData quota.exceeded 事件数据
+type QuotaExceededData struct {
+	Scope     string     `json:"scope"` // QuotaScope* 之一
+	Limit     int        `json:"limit"`
+	MailboxID string     `json:"mailboxId,omitempty"`
+	UserID    string     `json:"userId,omitempty"`
+	OrgID     string     `json:"orgId,omitempty"`
+	ResetAt   *time.Time `json:"resetAt,omitempty"` // 发信配额恢复时间
+}
+
+// NotifyMailboxCreated 邮箱创建后触发 mailbox.created（公开收件箱不触发）
+func (s *WebhookService) NotifyMailboxCreated(mailbox *domain.Mailbox) {
+	data := MailboxCreatedData{
+		MailboxID: mailbox.ID,
+		Address:   mailbox.Address,
+		ExpiresAt: mailbox.ExpiresAt,
+	}
+	if domain.InOrg(mailbox.OrgID) {
+		data.OrgID = *mailbox.OrgID
+	}
+	_ = s.trigger(context.Background(), mailboxOwner(mailbox), mailbox.ID, mailbox, domain.WebhookEventMailboxCreated, data)
+}
+
+// NotifyMessageDeleted 通过 API 删除邮件或清空邮箱后触发 message.deleted
+//
+// messageID 为空表示清空邮箱，count 为删除数量。
+func (s *WebhookService) NotifyMessageDeleted(mailboxID, messageID string, count int) {
+	ctx := context.Background()
+	mailbox, err := s.store.GetMailbox(ctx, mailboxID)
+	if err != nil {
+		return
+	}
+	_ = s.trigger(ctx, mailboxOwner(mailbox), mailbox.ID, mailbox, domain.WebhookEventMessageDeleted, MessageDeletedData{
+		MailboxID: mailbox.ID,
+		MessageID: messageID,
+		Count:     count,
+		Cleared:   messageID == "",
+	})
+}
+
+// NotifyDomainVerified 用户域名通过验证后触发 domain.verified（组织域名同时发给组织 Webhooks）
+func (s *WebhookService) NotifyDomainVerified(userDomain *domain.UserDomain) {
+	data := DomainVerifiedData{
+		DomainID:   userDomain.ID,
+		Domain:     userDomain.Domain,
+		VerifiedAt: userDomain.VerifiedAt,
+	}
+	if domain.InOrg(userDomain.OrgID) {
+		data.OrgID = *userDomain.OrgID
+	}
+	_ = s.triggerOwner(context.Background(), userDomain.UserID, data.OrgID, domain.WebhookEventDomainVerified, data)
+}
+
+// NotifyQuotaExceeded 配额用尽时触发 quota.exceeded
+//
+// 同一配额对象在 QuotaEventInterval 内只触发一次；带邮箱的事件同时发给组织和邮箱 Webhooks。
+func (s *WebhookService) NotifyQuotaExceeded(data QuotaExceededData) {
+	if !s.allowQuotaEvent(quotaEventKey(data), time.Now()) {
+		return
+	}
+	ctx := context.Background()
+	if data.MailboxID != "" {
+		mailbox, err := s.store.GetMailbox(ctx, data.MailboxID)
+		if err != nil {
+			return
+		}
+		_ = s.trigger(ctx, mailboxOwner(mailbox), mailbox.ID, mailbox, domain.WebhookEventQuotaExceeded, data)
+		return
+	}
+	_ = s.triggerOwner(ctx, data.UserID, data.OrgID, domain.WebhookEventQuotaExceeded, data)
+}
+
+// quotaEventKey 配额对象：组织、用户或邮箱
+func quotaEventKey(data QuotaExceededData) string {
+	switch data.Scope {
+	case QuotaScopeOrgMailboxes:
+		return data.Scope + ":" + data.OrgID
+	case QuotaScopeUserMailboxes, QuotaScopeUserSend:
+		return data.Scope + ":" + data.UserID
+	}
+	return data.Scope + ":" + data.MailboxID
+}
+
+// allowQuotaEvent 记录并判断配额对象是否可以再次触发事件，顺带清理过期记录
+func (s *WebhookService) allowQuotaEvent(key string, now time.Time) bool {
+	s.quotaMu.Lock()
+	defer s.quotaMu.Unlock()
+	if s.quotaNotified == nil {
+		s.quotaNotified = make(map[string]time.Time)
+	}
+	for k, at := range s.quotaNotified {
+		if now.Sub(at) >= QuotaEventInterval {
+			delete(s.quotaNotified, k)
+		}
+	}
+	if _, ok := s.quotaNotified[key]; ok {
+		return false
+	}
+	s.quotaNotified[key] = now
+	return true
+}
+
+// triggerOwner 触发与邮箱无关的事件：发给用户的个人 Webhooks，orgID 非空时同时发给组织 Webhooks
+func (s *WebhookService) triggerOwner(ctx context.Context, userID, orgID string, eventType domain.WebhookEventType, data interface{}) error {
+	webhooks, err := s.personalWebhooks(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if orgID != "" {
+		orgWebhooks, err := s.store.ListWebhooksByOrgID(ctx, orgID)
+		if err != nil {
+			return err
+		}
+		webhooks = append(webhooks, orgWebhooks...)
+	}
+	s.dispatch(ctx, webhooks, "", eventType, data)
+	return nil
+}
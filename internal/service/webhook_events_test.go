@@ -0,0 +1,86 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestWebhookService_StateEvents(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	recorder := &eventRecorder{}
+	server := httptest.NewServer(recorder)
+	t.Cleanup(server.Close)
+
+	webhooks := NewWebhookService(store)
+	userID := "user-1"
+	mailbox := &domain.Mailbox{ID: "mb-1", Address: "qa@temp.mail", UserID: &userID, CreatedAt: time.Now()}
+	require.NoError(t, store.SaveMailbox(t.Context(), mailbox))
+
+	t.Run("订阅不支持的事件", func(t *testing.T) {
+		_, err := webhooks.CreateWebhook(t.Context(), CreateWebhookInput{UserID: userID, URL: server.URL + "/bad", Events: []string{"new_mail"}})
+		assert.ErrorIs(t, err, ErrWebhookEventInvalid)
+	})
+
+	webhook, err := webhooks.CreateWebhook(t.Context(), CreateWebhookInput{
+		UserID: userID, URL: server.URL + "/user",
+		Events: []string{"mailbox.created", "message.deleted", "domain.verified", "quota.exceeded"},
+	})
+	require.NoError(t, err)
+	_, err = webhooks.UpdateWebhook(t.Context(), webhook.ID, UpdateWebhookInput{Events: []string{"mail.received", "nope"}})
+	assert.ErrorIs(t, err, ErrWebhookEventInvalid)
+
+	expect := func(t *testing.T, events ...string) {
+		t.Helper()
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual(events, recorder.get("/user"))
+		}, 2*time.Second, 10*time.Millisecond, "收到 %v", recorder.get("/user"))
+	}
+
+	t.Run("邮箱创建和删除邮件", func(t *testing.T) {
+		webhooks.NotifyMailboxCreated(mailbox)
+		expect(t, "mailbox.created")
+
+		messages := NewMessageService(store)
+		messages.SetMessageDeletedNotifier(webhooks)
+		msg, err := messages.Create(t.Context(), CreateMessageInput{MailboxID: "mb-1", From: "a@example.com", Subject: "hi", Text: "hello"})
+		require.NoError(t, err)
+		require.NoError(t, messages.Remove(t.Context(), "mb-1", msg.ID))
+		expect(t, "mailbox.created", "message.deleted")
+
+		// 邮箱为空时清空不触发
+		_, err = messages.RemoveAll(t.Context(), "mb-1")
+		require.NoError(t, err)
+		expect(t, "mailbox.created", "message.deleted")
+	})
+
+	t.Run("域名验证和配额用尽", func(t *testing.T) {
+		now := time.Now()
+		webhooks.NotifyDomainVerified(&domain.UserDomain{ID: "d-1", UserID: userID, Domain: "mail.example.com", VerifiedAt: &now})
+		expect(t, "mailbox.created", "message.deleted", "domain.verified")
+
+		quota := QuotaExceededData{Scope: QuotaScopeMailboxSend, Limit: 10, MailboxID: "mb-1"}
+		webhooks.NotifyQuotaExceeded(quota)
+		webhooks.NotifyQuotaExceeded(quota)
+		expect(t, "mailbox.created", "message.deleted", "domain.verified", "quota.exceeded")
+	})
+}
+
+func TestWebhookService_QuotaEventInterval(t *testing.T) {
+	webhooks := NewWebhookService(memory.NewStore(time.Hour))
+	now := time.Now()
+
+	assert.True(t, webhooks.allowQuotaEvent("send.mailbox:mb-1", now))
+	assert.False(t, webhooks.allowQuotaEvent("send.mailbox:mb-1", now.Add(time.Minute)), "间隔内只触发一次")
+	assert.True(t, webhooks.allowQuotaEvent("send.mailbox:mb-2", now), "不同配额对象分别计算")
+	assert.True(t, webhooks.allowQuotaEvent("send.mailbox:mb-1", now.Add(QuotaEventInterval)))
+
+	assert.Equal(t, "send.user:user-1", quotaEventKey(QuotaExceededData{Scope: QuotaScopeUserSend, UserID: "user-1", MailboxID: "mb-1"}))
+	assert.Equal(t, "org.mailboxes:org-1", quotaEventKey(QuotaExceededData{Scope: QuotaScopeOrgMailboxes, OrgID: "org-1"}))
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// 自定义模板的限制
+const (
+	MaxWebhookTemplateSize = 16 << 10  // 模板长度上限（字节）
+	maxWebhookPayloadSize  = 256 << 10 // 模板输出上限，超出视为执行失败
+)
+
+// WebhookSignatureTolerance 校验签名时允许的时间戳偏差
+const WebhookSignatureTolerance = 5 * time.Minute
+
+var (
+	ErrWebhookPayloadFormat   = errors.New("unsupported webhook payload format")
+	ErrWebhookTemplateInvalid = errors.New("invalid webhook payload template")
+	ErrWebhookSignature       = errors.New("invalid webhook signature")
+	ErrWebhookTestEvent       = errors.New("unsupported webhook test event")
+)
+
+// WebhookTemplateError 自定义模板无法解析、执行失败或输出不是 JSON（errors.Is 匹配 ErrWebhookTemplateInvalid）
+type WebhookTemplateError struct {
+	Err error
+}
+
+func (e *WebhookTemplateError) Error() string {
+	return ErrWebhookTemplateInvalid.Error() + ": " + e.Err.Error()
+}
+
+func (e *WebhookTemplateError) Is(target error) bool {
+	return target == ErrWebhookTemplateInvalid
+}
+
+// WebhookAttachment 回调中的附件信息（不含内容）
+type WebhookAttachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// MailReceivedFullData full 格式的 mail.received 事件数据
+type MailReceivedFullData struct {
+	MailReceivedData
+	Text         string               `json:"text,omitempty"`
+	HTML         string               `json:"html,omitempty"`
+	Attachments  []WebhookAttachment  `json:"attachments,omitempty"`
+	Verification *domain.Verification `json:"verification,omitempty"` // 提取的验证码和验证链接
+}
+
+// WebhookTemplateData 自定义模板可以使用的数据
+//
+// Data 与 headers 格式的 data 相同；Message 为 mail.received 事件的邮件（含正文），其他事件为 nil。
+// 模板中可用 {{json .Data}} 输出转义后的 JSON 值。
+type WebhookTemplateData struct {
+	ID        string
+	Event     string
+	Timestamp time.Time
+	Test      bool
+	Data      interface{}
+	Message   *domain.Message
+}
+
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// normalizePayload 校验回调内容格式和模板，返回规范化的格式（为空时使用 headers）
+func normalizePayload(format, tmpl string) (string, string, error) {
+	switch format {
+	case "", domain.WebhookPayloadHeaders:
+		return domain.WebhookPayloadHeaders, "", nil
+	case domain.WebhookPayloadFull:
+		return format, "", nil
+	case domain.WebhookPayloadCustom:
+		if strings.TrimSpace(tmpl) == "" || len(tmpl) > MaxWebhookTemplateSize {
+			return "", "", &WebhookTemplateError{Err: fmt.Errorf("template must be 1 to %d bytes", MaxWebhookTemplateSize)}
+		}
+		// 用示例事件试运行一次，尽早发现模板错误和非 JSON 输出
+		if _, err := renderTemplate(tmpl, sampleEvent(domain.WebhookEventMailReceived)); err != nil {
+			return "", "", err
+		}
+		return format, tmpl, nil
+	}
+	return "", "", ErrWebhookPayloadFormat
+}
+
+// renderPayload 按 Webhook 的内容格式生成回调内容
+func renderPayload(webhook *domain.Webhook, event domain.WebhookEvent) ([]byte, error) {
+	switch webhook.PayloadFormat {
+	case domain.WebhookPayloadFull:
+		if data, ok := event.Data.(MailReceivedData); ok && data.message != nil {
+			event.Data = fullMailData(data)
+		}
+		return json.Marshal(event)
+	case domain.WebhookPayloadCustom:
+		return renderTemplate(webhook.PayloadTemplate, event)
+	}
+	return json.Marshal(event)
+}
+
+// fullMailData 补充邮件正文、附件列表和验证码
+func fullMailData(data MailReceivedData) MailReceivedFullData {
+	message := data.message
+	full := MailReceivedFullData{
+		MailReceivedData: data,
+		Text:             message.Text,
+		HTML:             message.HTML,
+		Verification:     message.Verification,
+	}
+	for _, att := range message.Attachments {
+		if att != nil {
+			full.Attachments = append(full.Attachments, WebhookAttachment{
+				ID: att.ID, Filename: att.Filename, ContentType: att.ContentType, Size: att.Size,
+			})
+		}
+	}
+	return full
+}
+
+// renderTemplate 执行自定义模板，输出必须是合法 JSON
+func renderTemplate(tmpl string, event domain.WebhookEvent) ([]byte, error) {
+	t, err := template.New("payload").Funcs(webhookTemplateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, &WebhookTemplateError{Err: err}
+	}
+	data := WebhookTemplateData{
+		ID:        event.ID,
+		Event:     string(event.Event),
+		Timestamp: event.Timestamp,
+		Test:      event.Test,
+		Data:      event.Data,
+	}
+	if mail, ok := event.Data.(MailReceivedData); ok {
+		data.Message = mail.message
+	}
+	var buf limitedBuffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, &WebhookTemplateError{Err: err}
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, &WebhookTemplateError{Err: errors.New("output is not valid JSON")}
+	}
+	return buf.Bytes(), nil
+}
+
+// limitedBuffer 超过 maxWebhookPayloadSize 后写入失败，防止模板无限输出
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > maxWebhookPayloadSize {
+		return 0, fmt.Errorf("output exceeds %d bytes", maxWebhookPayloadSize)
+	}
+	return b.Buffer.Write(p)
+}
+
+// SignWebhookPayload 计算 X-Tempmail-Signature：t=<Unix 秒>,v1=<HMAC-SHA256(secret, "<t>.<payload>") 十六进制>
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts + "."))
+	h.Write(payload)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyWebhookSignature 校验 X-Tempmail-Signature，时间戳与 now 相差超过 tolerance 时视为重放
+func VerifyWebhookSignature(secret, header string, payload []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrWebhookSignature
+	}
+	if skew := now.Sub(time.Unix(timestamp, 0)); skew > tolerance || skew < -tolerance {
+		return ErrWebhookSignature
+	}
+	expected := SignWebhookPayload(secret, timestamp, payload)
+	_, want, _ := strings.Cut(expected, ",v1=")
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(want)) {
+			return nil
+		}
+	}
+	return ErrWebhookSignature
+}
+
+// sampleEvent 测试投递和模板校验使用的示例事件
+func sampleEvent(eventType domain.WebhookEventType) domain.WebhookEvent {
+	now := time.Now().UTC().Truncate(time.Second)
+	var data interface{}
+	switch eventType {
+	case domain.WebhookEventMailReceived:
+		message := &domain.Message{
+			ID:         "00000000-0000-0000-0000-000000000001",
+			MailboxID:  "00000000-0000-0000-0000-000000000002",
+			From:       "sender@example.com",
+			To:         "test@temp.mail",
+			Subject:    "Your verification code",
+			Text:       "Your verification code is 123456.",
+			HTML:       "<p>Your verification code is <b>123456</b>.</p>",
+			ReceivedAt: now,
+			Verification: &domain.Verification{
+				Code: "123456", Codes: []string{"123456"},
+			},
+		}
+		data = MailReceivedData{
+			MessageID:  message.ID,
+			MailboxID:  message.MailboxID,
+			From:       message.From,
+			To:         message.To,
+			Subject:    message.Subject,
+			ReceivedAt: now,
+			message:    message,
+		}
+	case domain.WebhookEventMailboxExpired:
+		data = MailboxExpiredData{
+			MailboxID: "00000000-0000-0000-0000-000000000002",
+			Address:   "test@temp.mail",
+			ExpiresAt: &now,
+		}
+	case domain.WebhookEventMailboxCreated:
+		expires := now.Add(24 * time.Hour)
+		data = MailboxCreatedData{
+			MailboxID: "00000000-0000-0000-0000-000000000002",
+			Address:   "test@temp.mail",
+			ExpiresAt: &expires,
+		}
+	case domain.WebhookEventMessageDeleted:
+		data = MessageDeletedData{
+			MailboxID: "00000000-0000-0000-0000-000000000002",
+			MessageID: "00000000-0000-0000-0000-000000000001",
+			Count:     1,
+		}
+	case domain.WebhookEventDomainVerified:
+		data = DomainVerifiedData{
+			DomainID:   "00000000-0000-0000-0000-000000000003",
+			Domain:     "mail.example.com",
+			VerifiedAt: &now,
+		}
+	case domain.WebhookEventQuotaExceeded:
+		reset := now.Add(SendQuotaWindow)
+		data = QuotaExceededData{
+			Scope:     QuotaScopeMailboxSend,
+			Limit:     50,
+			MailboxID: "00000000-0000-0000-0000-000000000002",
+			ResetAt:   &reset,
+		}
+	default:
+		data = map[string]interface{}{"sample": true}
+	}
+	return domain.WebhookEvent{
+		ID:        "00000000-0000-0000-0000-000000000000",
+		Event:     eventType,
+		Timestamp: now,
+		Data:      data,
+		Test:      true,
+	}
+}
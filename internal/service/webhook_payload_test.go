@@ -0,0 +1,136 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	payload := []byte(`{"event":"mail.received"}`)
+	now := time.Unix(1767225600, 0)
+	header := SignWebhookPayload("secret-secret-secret", now.Unix(), payload)
+	assert.Regexp(t, `^t=1767225600,v1=[0-9a-f]{64}$`, header)
+
+	assert.NoError(t, VerifyWebhookSignature("secret-secret-secret", header, payload, now.Add(time.Minute), WebhookSignatureTolerance))
+	assert.ErrorIs(t, VerifyWebhookSignature("other-secret-value", header, payload, now, WebhookSignatureTolerance), ErrWebhookSignature)
+	assert.ErrorIs(t, VerifyWebhookSignature("secret-secret-secret", header, []byte(`{}`), now, WebhookSignatureTolerance), ErrWebhookSignature)
+	assert.ErrorIs(t, VerifyWebhookSignature("secret-secret-secret", header, payload, now.Add(time.Hour), WebhookSignatureTolerance), ErrWebhookSignature, "过期的时间戳视为重放")
+	assert.ErrorIs(t, VerifyWebhookSignature("secret-secret-secret", "sha256=abc", payload, now, WebhookSignatureTolerance), ErrWebhookSignature)
+}
+
+func TestWebhookPayloadFormats(t *testing.T) {
+	event := sampleEvent(domain.WebhookEventMailReceived)
+
+	t.Run("headers 格式不含正文", func(t *testing.T) {
+		payload, err := renderPayload(&domain.Webhook{PayloadFormat: domain.WebhookPayloadHeaders}, event)
+		require.NoError(t, err)
+		assert.Contains(t, string(payload), `"subject":"Your verification code"`)
+		assert.NotContains(t, string(payload), `"text"`)
+	})
+
+	t.Run("full 格式包含正文和验证码", func(t *testing.T) {
+		payload, err := renderPayload(&domain.Webhook{PayloadFormat: domain.WebhookPayloadFull}, event)
+		require.NoError(t, err)
+		var decoded struct {
+			Data MailReceivedFullData `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(payload, &decoded))
+		assert.Equal(t, "Your verification code is 123456.", decoded.Data.Text)
+		assert.Equal(t, "123456", decoded.Data.Verification.Code)
+		assert.Equal(t, event.Data.(MailReceivedData).MessageID, decoded.Data.MessageID)
+	})
+
+	t.Run("自定义模板", func(t *testing.T) {
+		tmpl := `{"text": {{json (printf "%s: %s" .Event .Data.Subject)}}, "code": {{json .Message.Verification.Code}}}`
+		format, normalized, err := normalizePayload(domain.WebhookPayloadCustom, tmpl)
+		require.NoError(t, err)
+		assert.Equal(t, domain.WebhookPayloadCustom, format)
+
+		payload, err := renderPayload(&domain.Webhook{PayloadFormat: format, PayloadTemplate: normalized}, event)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"text":"mail.received: Your verification code","code":"123456"}`, string(payload))
+	})
+
+	t.Run("模板和格式校验", func(t *testing.T) {
+		format, _, err := normalizePayload("", "")
+		require.NoError(t, err)
+		assert.Equal(t, domain.WebhookPayloadHeaders, format)
+
+		_, _, err = normalizePayload("xml", "")
+		assert.ErrorIs(t, err, ErrWebhookPayloadFormat)
+		for _, tmpl := range []string{"", "{{.Nope", `{"a": {{.Data.Missing}}}`, `not json {{.Event}}`, `{{range 100000000}}xxxx{{end}}`} {
+			_, _, err = normalizePayload(domain.WebhookPayloadCustom, tmpl)
+			assert.ErrorIs(t, err, ErrWebhookTemplateInvalid, tmpl)
+		}
+	})
+}
+
+func TestWebhookService_TestWebhook(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	var (
+		gotSignature, gotTest string
+		gotBody               []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get("X-Tempmail-Signature")
+		gotTest = r.Header.Get("X-Webhook-Test")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	webhooks := NewWebhookService(store)
+	webhook, err := webhooks.CreateWebhook(t.Context(), CreateWebhookInput{
+		UserID: "user-1", URL: server.URL, Events: []string{"mailbox.expired", "mail.received"},
+		Secret: "0123456789abcdef", PayloadFormat: domain.WebhookPayloadFull,
+	})
+	require.NoError(t, err)
+
+	t.Run("发送签名的示例事件", func(t *testing.T) {
+		delivery, err := webhooks.TestWebhook(t.Context(), webhook, "mail.received")
+		require.NoError(t, err)
+		assert.True(t, delivery.Success)
+		assert.Equal(t, http.StatusNoContent, delivery.StatusCode)
+		assert.Nil(t, delivery.NextRetry)
+		assert.Equal(t, "true", gotTest)
+		assert.NoError(t, VerifyWebhookSignature("0123456789abcdef", gotSignature, gotBody, time.Now(), WebhookSignatureTolerance))
+		assert.Contains(t, string(gotBody), `"test":true`)
+		assert.Contains(t, string(gotBody), `"text":"Your verification code is 123456."`)
+
+		deliveries, err := webhooks.GetDeliveries(t.Context(), webhook.ID, 10)
+		require.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+	})
+
+	t.Run("默认使用订阅的第一个事件", func(t *testing.T) {
+		delivery, err := webhooks.TestWebhook(t.Context(), webhook, "")
+		require.NoError(t, err)
+		assert.Equal(t, domain.WebhookEventMailboxExpired, delivery.Event)
+
+		_, err = webhooks.TestWebhook(t.Context(), webhook, "nope")
+		assert.ErrorIs(t, err, ErrWebhookTestEvent)
+	})
+
+	t.Run("更新格式和轮换密钥", func(t *testing.T) {
+		oldSecret := webhook.Secret
+		updated, err := webhooks.UpdateWebhook(t.Context(), webhook.ID, UpdateWebhookInput{
+			PayloadFormat: domain.WebhookPayloadCustom, PayloadTemplate: `{"id": {{json .ID}}}`, RotateSecret: true,
+		})
+		require.NoError(t, err)
+		assert.NotEqual(t, oldSecret, updated.Secret)
+		assert.Equal(t, domain.WebhookPayloadCustom, updated.PayloadFormat)
+
+		_, err = webhooks.UpdateWebhook(t.Context(), webhook.ID, UpdateWebhookInput{PayloadTemplate: "{{"})
+		assert.ErrorIs(t, err, ErrWebhookTemplateInvalid)
+	})
+}
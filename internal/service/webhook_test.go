@@ -0,0 +1,220 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// webhookReceiver 记录收到的 mail.received 投递
+type webhookReceiver struct {
+	mu       sync.Mutex
+	received []MailReceivedData
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var event struct {
+		Data MailReceivedData `json:"data"`
+	}
+	_ = json.NewDecoder(req.Body).Decode(&event)
+	r.mu.Lock()
+	r.received = append(r.received, event.Data)
+	r.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *webhookReceiver) messageIDs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, 0, len(r.received))
+	for _, data := range r.received {
+		ids = append(ids, data.MessageID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestWebhookService_Backfill(t *testing.T) {
+	setup := func(t *testing.T) (*memory.Store, *WebhookService, *webhookReceiver, string) {
+		t.Helper()
+		store := memory.NewStore(24 * time.Hour)
+		receiver := &webhookReceiver{}
+		server := httptest.NewServer(receiver)
+		t.Cleanup(server.Close)
+
+		webhooks := NewWebhookService(store)
+		webhooks.SetReplayWindow(5 * time.Minute)
+
+		userID := "user-1"
+		now := time.Now()
+		expires := now.Add(time.Hour)
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-1", Address: "a@temp.mail", UserID: &userID, CreatedAt: now, ExpiresAt: &expires}))
+		for id, received := range map[string]time.Time{
+			"recent-1": now.Add(-time.Minute),
+			"recent-2": now.Add(-2 * time.Minute),
+			"stale":    now.Add(-time.Hour),
+		} {
+			require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{ID: id, MailboxID: "mb-1", Subject: id, ReceivedAt: received, CreatedAt: received}))
+		}
+		return store, webhooks, receiver, server.URL
+	}
+
+	t.Run("创建后收信前订阅的 Webhook 补发最近的邮件", func(t *testing.T) {
+		_, webhooks, receiver, url := setup(t)
+		webhook, err := webhooks.CreateWebhook(t.Context(), CreateWebhookInput{
+			UserID: "user-1", URL: url, Events: []string{string(domain.WebhookEventMailReceived)}, Backfill: true,
+		})
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool { return len(receiver.messageIDs()) == 2 }, 2*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"recent-1", "recent-2"}, receiver.messageIDs())
+		receiver.mu.Lock()
+		for _, data := range receiver.received {
+			assert.True(t, data.Replayed)
+		}
+		receiver.mu.Unlock()
+
+		assert.Eventually(t, func() bool {
+			deliveries, _ := webhooks.GetDeliveries(t.Context(), webhook.ID, 10)
+			return len(deliveries) == 2
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("已投递过的邮件不重复补发", func(t *testing.T) {
+		store, webhooks, receiver, url := setup(t)
+		webhook, err := webhooks.CreateWebhook(t.Context(), CreateWebhookInput{
+			UserID: "user-1", URL: url, Events: []string{string(domain.WebhookEventMailReceived)},
+		})
+		require.NoError(t, err)
+		payload, err := json.Marshal(domain.WebhookEvent{Event: domain.WebhookEventMailReceived, Data: MailReceivedData{MessageID: "recent-1"}})
+		require.NoError(t, err)
+		require.NoError(t, store.RecordDelivery(t.Context(), &domain.WebhookDelivery{
+			ID: "d-1", WebhookID: webhook.ID, Event: domain.WebhookEventMailReceived, Payload: string(payload), Success: true,
+		}))
+
+		queued, err := webhooks.Backfill(t.Context(), webhook)
+		require.NoError(t, err)
+		assert.Equal(t, 1, queued)
+		assert.Eventually(t, func() bool { return len(receiver.messageIDs()) == 1 }, 2*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"recent-2"}, receiver.messageIDs())
+	})
+
+	t.Run("未订阅 mail.received 或未要求补发时不投递", func(t *testing.T) {
+		_, webhooks, receiver, url := setup(t)
+		_, err := webhooks.CreateWebhook(t.Context(), CreateWebhookInput{
+			UserID: "user-1", URL: url, Events: []string{string(domain.WebhookEventMailboxCreated)}, Backfill: true,
+		})
+		require.NoError(t, err)
+		_, err = webhooks.CreateWebhook(t.Context(), CreateWebhookInput{
+			UserID: "user-1", URL: url, Events: []string{string(domain.WebhookEventMailReceived)},
+		})
+		require.NoError(t, err)
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Empty(t, receiver.messageIDs())
+	})
+}
+
+func TestWebhookService_DeadLetters(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(server.Close)
+
+	webhooks := NewWebhookService(store)
+	webhook, err := webhooks.CreateWebhook(t.Context(), CreateWebhookInput{UserID: "user-1", URL: server.URL, Events: []string{"mail.received"}})
+	require.NoError(t, err)
+	payload := []byte(`{"event":"mail.received"}`)
+
+	t.Run("可重试的失败不进入死信", func(t *testing.T) {
+		delivery := webhooks.send(t.Context(), webhook, domain.WebhookEventMailReceived, payload, "", 1, false)
+		assert.False(t, delivery.Success)
+		assert.NotNil(t, delivery.NextRetry)
+		assert.False(t, delivery.DeadLetter)
+
+		dead, err := webhooks.ListDeadLetters(t.Context(), webhook.ID, 0)
+		require.NoError(t, err)
+		assert.Empty(t, dead)
+	})
+
+	dead := webhooks.send(t.Context(), webhook, domain.WebhookEventMailReceived, payload, "", 5, false)
+
+	t.Run("重试用尽后进入死信", func(t *testing.T) {
+		assert.True(t, dead.DeadLetter)
+		assert.Nil(t, dead.NextRetry)
+
+		listed, err := webhooks.ListDeadLetters(t.Context(), webhook.ID, 0)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, dead.ID, listed[0].ID)
+
+		all, err := webhooks.ListDeadLetters(t.Context(), "", 0)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("重新投递", func(t *testing.T) {
+		status.Store(http.StatusOK)
+		redelivered, err := webhooks.Redeliver(t.Context(), webhook, dead.ID)
+		require.NoError(t, err)
+		assert.True(t, redelivered.Success)
+		assert.NotEqual(t, dead.ID, redelivered.ID)
+		assert.Equal(t, string(payload), redelivered.Payload)
+
+		listed, err := webhooks.ListDeadLetters(t.Context(), webhook.ID, 0)
+		require.NoError(t, err)
+		assert.Empty(t, listed, "已重新投递的记录不再列入死信")
+
+		original, err := store.GetDelivery(t.Context(), dead.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, original.RedeliveredAt)
+	})
+
+	t.Run("只能重新投递本 Webhook 的记录", func(t *testing.T) {
+		other, err := webhooks.CreateWebhook(t.Context(), CreateWebhookInput{UserID: "user-1", URL: server.URL, Events: []string{"mail.received"}})
+		require.NoError(t, err)
+		_, err = webhooks.Redeliver(t.Context(), other, dead.ID)
+		assert.ErrorIs(t, err, ErrWebhookDeliveryNotFound)
+		_, err = webhooks.Redeliver(t.Context(), webhook, "missing")
+		assert.ErrorIs(t, err, ErrWebhookDeliveryNotFound)
+	})
+}
+
+func TestWebhookService_RetryClearsPending(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	webhooks := NewWebhookService(store)
+	webhook, err := webhooks.CreateWebhook(t.Context(), CreateWebhookInput{UserID: "user-1", URL: server.URL, Events: []string{"mail.received"}})
+	require.NoError(t, err)
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, store.RecordDelivery(t.Context(), &domain.WebhookDelivery{
+		ID: "d-1", WebhookID: webhook.ID, Event: domain.WebhookEventMailReceived, Payload: `{}`, Attempts: 1, NextRetry: &past,
+	}))
+
+	require.NoError(t, webhooks.RetryFailedDeliveries(t.Context()))
+	assert.Eventually(t, func() bool { return hits.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	retried, err := store.GetDelivery(t.Context(), "d-1")
+	require.NoError(t, err)
+	assert.Nil(t, retried.NextRetry, "已取出重试的记录不再待重试")
+}
@@ -0,0 +1,46 @@
+package smtp
+
+import (
+	"net/mail"
+	"strings"
+
+	gosmtp "github.com/emersion/go-smtp"
+
+	"tempmail/backend/internal/dkim"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/mailauth"
+)
+
+// errAuthRejected 发件人认证未通过且配置为拒收
+var errAuthRejected = &gosmtp.SMTPError{
+	Code:         550,
+	EnhancedCode: gosmtp.EnhancedCode{5, 7, 1},
+	Message:      "message rejected: sender authentication (SPF/DMARC) failed",
+}
+
+// authenticate 检查 SPF 和 DMARC，未设置认证器时返回 nil
+func (s *session) authenticate(header mail.Header, verification dkim.Verification) *domain.AuthenticationResults {
+	if s.backend.authenticator == nil {
+		return nil
+	}
+	return s.backend.authenticator.Evaluate(s.context(), mailauth.Input{
+		IP:         s.remoteIP,
+		Helo:       s.helo,
+		MailFrom:   s.fromAddress,
+		HeaderFrom: headerFromDomain(header),
+		DKIM:       verification,
+	})
+}
+
+// headerFromDomain From 头中（第一个）地址的域名，无法解析时为空
+func headerFromDomain(header mail.Header) string {
+	addrs, err := header.AddressList("From")
+	if err != nil || len(addrs) == 0 {
+		return ""
+	}
+	_, host, ok := strings.Cut(addrs[0].Address, "@")
+	if !ok {
+		return ""
+	}
+	return host
+}
@@ -1,16 +1,27 @@
 package smtp
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"mime"
+	"net"
 	"strings"
+	"sync/atomic"
 
 	gosmtp "github.com/emersion/go-smtp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"tempmail/backend/internal/dkim"
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/mailauth"
 	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/spam"
+	"tempmail/backend/internal/storage/filesystem"
+	"tempmail/backend/internal/tracing"
 	"tempmail/backend/internal/websocket"
 )
 
@@ -19,6 +30,54 @@ var (
 	ErrMailboxNotFound = errors.New("mailbox not found")
 )
 
+// DefaultMaxMessageBytes 默认单封邮件大小上限
+const DefaultMaxMessageBytes = 10 << 20
+
+// DefaultMaxRecipients 默认单次事务最多投递的本系统收件人数
+const DefaultMaxRecipients = 25
+
+// errTooManyRecipients 超出单次事务的收件人上限，发件方会在新的事务中重试这些收件人
+var errTooManyRecipients = &gosmtp.SMTPError{
+	Code:         452,
+	EnhancedCode: gosmtp.EnhancedCode{4, 5, 3},
+	Message:      "too many recipients, try again in another transaction",
+}
+
+// errMailboxSuspended 邮箱因滥用被停用，拒绝投递（含发往其别名的邮件）
+var errMailboxSuspended = &gosmtp.SMTPError{
+	Code:         550,
+	EnhancedCode: gosmtp.EnhancedCode{5, 7, 1},
+	Message:      "mailbox suspended",
+}
+
+// errMailboxFull 邮箱邮件数量已达所属等级上限，拒绝投递（发件方可稍后重试或放弃）
+var errMailboxFull = &gosmtp.SMTPError{
+	Code:         552,
+	EnhancedCode: gosmtp.EnhancedCode{5, 2, 2},
+	Message:      "mailbox full",
+}
+
+var (
+	// errDomainExpired 用户域名过了宽限期
+	errDomainExpired = &gosmtp.SMTPError{
+		Code:         550,
+		EnhancedCode: gosmtp.EnhancedCode{5, 7, 1},
+		Message:      "domain expired - mail not accepted",
+	}
+	// errRelayDenied 收件域名不由本系统管理
+	errRelayDenied = &gosmtp.SMTPError{
+		Code:         550,
+		EnhancedCode: gosmtp.EnhancedCode{5, 7, 1},
+		Message:      "relay access denied - domain not managed by this server",
+	}
+	// errRecipientNotFound 域名由本系统管理，但邮箱不存在
+	errRecipientNotFound = &gosmtp.SMTPError{
+		Code:         550,
+		EnhancedCode: gosmtp.EnhancedCode{5, 1, 1},
+		Message:      "recipient mailbox not found",
+	}
+)
+
 // Backend 实现 go-smtp 的 Backend 接口。
 //
 // 【安全说明】
@@ -42,7 +101,51 @@ type Backend struct {
 	systemDomains     *service.SystemDomainService
 	userDomainService *service.UserDomainService
 	wsHub             *websocket.Hub
-	fsStore           FilesystemStore // 文件系统存储接口
+	fsStore           FilesystemStore                  // 文件系统存储接口
+	maintenance       MaintenanceChecker               // 维护模式状态（可选）
+	ingest            IngestRecorder                   // 入库结果上报（可选）
+	lists             *service.DistributionListService // 分发列表（可选）
+	spamFilter        *spam.Filter                     // 垃圾邮件评分（可选）
+	users             UserLookup                       // 邮箱所有者查询（可选，用户级垃圾邮件阈值）
+	scanner           *service.AttachmentScanService   // 附件病毒扫描（可选）
+	dkimVerifier      *dkim.Verifier                   // DKIM 签名验证（可选）
+	authenticator     *mailauth.Authenticator          // SPF/DMARC 认证（可选）
+	sinks             *service.SinkService             // 域名黑洞模式（可选）
+	catchAll          *service.CatchAllService         // 用户域名通配收件（可选）
+	whitelist         *service.DomainWhitelistService  // 白名单模式域名的收件人检查（可选）
+	forwards          *service.ForwardingService       // 转发到外部地址（可选）
+	metrics           RecipientMetrics                 // 收件人数指标（可选）
+	headers           HeaderAllowlist                  // 收信时保存的头名单（可选，未设置时不保存）
+	sessions          *SessionRegistry                 // 活跃会话登记表
+	baseCtx           context.Context                  // 会话上下文的父上下文（服务关闭时取消）
+	maxMessageBytes   atomic.Int64                     // 单封邮件大小上限（系统配置修改后即时生效）
+	maxRecipients     int                              // 单次事务最多投递的收件人数
+	stableIDs         bool                             // 附件 ID 由内容哈希派生（回放模式）
+	guard             *inboundGuard                    // 收信限流和灰名单（可选）
+	rejections        RejectionMetrics                 // 收信保护拒绝指标（可选）
+	mailFlow          MailFlowRecorder                 // 收信流量统计（可选）
+	draining          atomic.Bool                      // 正在排空（关闭前拒绝新会话和新事务）
+}
+
+// IngestRecorder 邮件入库结果上报接口
+type IngestRecorder interface {
+	RecordIngest(err error)
+}
+
+// RecipientMetrics 单次事务收件人数指标
+type RecipientMetrics interface {
+	RecordRecipientsPerTransaction(count int)
+	RecordRecipientDeferred()
+}
+
+// HeaderAllowlist 收信时保存到邮件上的头名单（由配置服务提供，可运行时修改）
+type HeaderAllowlist interface {
+	CapturedHeaders() []string
+}
+
+// MaintenanceChecker 维护模式状态接口
+type MaintenanceChecker interface {
+	MaintenanceState() (readOnly bool, message string)
 }
 
 // FilesystemStore 文件系统存储接口
@@ -52,6 +155,8 @@ type FilesystemStore interface {
 	SaveAttachment(mailboxID, messageID, attachmentID string, attachment *domain.Attachment) (string, error)
 	GetMessageRaw(mailboxID, messageID string) ([]byte, error)
 	GetMessageMetadata(mailboxID, messageID string) (*domain.Message, error)
+	CreateSpool() (*filesystem.Spool, error)
+	BlobStager
 }
 
 // NewBackend 创建 SMTP Backend。
@@ -64,7 +169,7 @@ func NewBackend(
 	wsHub *websocket.Hub,
 	fsStore FilesystemStore,
 ) *Backend {
-	return &Backend{
+	b := &Backend{
 		mailboxes:         mailboxes,
 		messages:          messages,
 		aliases:           aliases,
@@ -72,18 +177,176 @@ func NewBackend(
 		userDomainService: userDomainService,
 		wsHub:             wsHub,
 		fsStore:           fsStore,
+		maxRecipients:     DefaultMaxRecipients,
+		sessions:          NewSessionRegistry(),
+	}
+	b.maxMessageBytes.Store(DefaultMaxMessageBytes)
+	return b
+}
+
+// SetMaintenanceChecker 设置维护模式状态来源
+func (b *Backend) SetMaintenanceChecker(checker MaintenanceChecker) {
+	b.maintenance = checker
+}
+
+// SetHeaderAllowlist 设置收信时保存的头名单来源
+func (b *Backend) SetHeaderAllowlist(allowlist HeaderAllowlist) {
+	b.headers = allowlist
+}
+
+// SetIngestRecorder 设置入库结果上报（用于公开状态页）
+func (b *Backend) SetIngestRecorder(recorder IngestRecorder) {
+	b.ingest = recorder
+}
+
+// SetDistributionLists 设置分发列表服务（收件人解析增加分发列表一步）
+func (b *Backend) SetDistributionLists(lists *service.DistributionListService) {
+	b.lists = lists
+}
+
+// SetSpamFilter 设置垃圾邮件评分（评分与原始邮件落盘并行进行）
+func (b *Backend) SetSpamFilter(filter *spam.Filter) {
+	b.spamFilter = filter
+}
+
+// SetDKIMVerifier 设置 DKIM 签名验证（验证与原始邮件落盘并行进行，结果保存在邮件上）
+func (b *Backend) SetDKIMVerifier(verifier *dkim.Verifier) {
+	b.dkimVerifier = verifier
+}
+
+// SetAuthenticator 设置 SPF/DMARC 发件人认证（结果保存在邮件上，未通过时按认证器的处理方式拒收或隔离）
+func (b *Backend) SetAuthenticator(authenticator *mailauth.Authenticator) {
+	b.authenticator = authenticator
+}
+
+// SetCatchAllService 设置用户域名通配收件（通配模式的域名接收发往不存在地址的邮件）
+func (b *Backend) SetCatchAllService(catchAll *service.CatchAllService) {
+	b.catchAll = catchAll
+}
+
+// SetDomainWhitelist 设置域名白名单服务（白名单模式的域名拒收不在白名单中的前缀）
+func (b *Backend) SetDomainWhitelist(whitelist *service.DomainWhitelistService) {
+	b.whitelist = whitelist
+}
+
+// SetForwardingService 设置邮箱转发（入库后为已确认的转发地址写入投递队列）
+func (b *Backend) SetForwardingService(forwards *service.ForwardingService) {
+	b.forwards = forwards
+}
+
+// SetSinkService 设置域名黑洞模式服务（开启黑洞模式的域名接收任意收件人，只累计统计）
+func (b *Backend) SetSinkService(sinks *service.SinkService) {
+	b.sinks = sinks
+}
+
+// SetMaxMessageBytes 设置单封邮件大小上限（超出时返回 552），可在运行时调用
+//
+// 对之后开始的事务生效；协议层上限（gosmtp.Server.MaxMessageBytes）应不小于此值。
+func (b *Backend) SetMaxMessageBytes(limit int64) {
+	if limit > 0 {
+		b.maxMessageBytes.Store(limit)
+	}
+}
+
+// SetMaxRecipients 设置单次事务最多投递的收件人数（超出的收件人返回 452）
+func (b *Backend) SetMaxRecipients(limit int) {
+	if limit > 0 {
+		b.maxRecipients = limit
+	}
+}
+
+// SetSessionRegistry 设置活跃会话登记表（与管理端查看会话的接口共用）
+func (b *Backend) SetSessionRegistry(sessions *SessionRegistry) {
+	if sessions != nil {
+		b.sessions = sessions
 	}
 }
 
+// Sessions 活跃会话登记表
+func (b *Backend) Sessions() *SessionRegistry {
+	return b.sessions
+}
+
+// SetRecipientMetrics 设置收件人数指标
+func (b *Backend) SetRecipientMetrics(metrics RecipientMetrics) {
+	b.metrics = metrics
+}
+
+// SetStableAttachmentIDs 附件 ID 改由内容哈希和序号派生
+//
+// 只供回放工具使用：同一封邮件每次解析得到相同的附件 ID，输出可与基准结果逐字比较。
+func (b *Backend) SetStableAttachmentIDs(enabled bool) {
+	b.stableIDs = enabled
+}
+
+// SetBaseContext 设置会话上下文的父上下文
+//
+// 每个会话的上下文由它派生：服务关闭时取消，进行中的存储调用随之中止，发件方会收到临时错误并稍后重试。
+func (b *Backend) SetBaseContext(ctx context.Context) {
+	b.baseCtx = ctx
+}
+
+// newSession 创建会话，会话上下文在 Logout 时取消
+func (b *Backend) newSession(parent context.Context) *session {
+	ctx, cancel := context.WithCancel(parent)
+	return &session{backend: b, ctx: ctx, cancel: cancel}
+}
+
+// startSpan 开始会话的 span（连接建立到 Logout），会话内的存储调用都是它的子 span
+func (s *session) startSpan(remoteAddr string) {
+	s.ctx, s.span = tracing.Start(s.ctx, "smtp.session",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("client.address", remoteAddr),
+			attribute.String("smtp.helo", s.helo),
+		),
+	)
+}
+
 // NewSession 创建新的 SMTP 会话。
+//
+// 会话登记到活跃会话表，连接关闭时（包括出错和强制关闭）由 Logout 移除。
 func (b *Backend) NewSession(c *gosmtp.Conn) (gosmtp.Session, error) {
-	return &session{
-		backend: b,
-	}, nil
+	parent := b.baseCtx
+	if parent == nil {
+		parent = context.Background()
+	}
+	var remoteIP net.IP
+	if c != nil {
+		if addr, ok := c.Conn().RemoteAddr().(*net.TCPAddr); ok {
+			remoteIP = addr.IP
+		}
+	}
+	// 排空期间监听器已关闭，关闭前已建立的连接不再开始新会话
+	if b.draining.Load() {
+		return nil, errShuttingDown
+	}
+	// 单 IP 并发连接数超限时不创建会话，连接上的命令都无法进行
+	if b.guard != nil && remoteIP != nil {
+		if !b.guard.acquireConn(remoteIP) {
+			return nil, b.reject(RejectReasonIPConnections, errTooManyConnections)
+		}
+	}
+
+	s := b.newSession(parent)
+	s.remoteIP = remoteIP
+	if c != nil {
+		conn := c.Conn()
+		s.tracked = b.sessions.open(conn.RemoteAddr(), c.Hostname(), conn.Close)
+		s.helo = c.Hostname()
+		s.startSpan(conn.RemoteAddr().String())
+	}
+	return s, nil
 }
 
 type session struct {
 	backend     *Backend
+	ctx         context.Context    // 会话上下文（直接构造的会话为空）
+	cancel      context.CancelFunc // 取消会话上下文
+	span        trace.Span         // 会话 span（直接构造的会话为空）
+	tracked     *trackedSession    // 活跃会话登记（直接构造的会话为空）
+	remoteIP    net.IP             // 连接 IP（SPF 检查用，直接构造的会话为空）
+	helo        string             // HELO/EHLO 名
 	fromAddress string
 	recipients  []recipient
 }
@@ -91,11 +354,48 @@ type session struct {
 type recipient struct {
 	address string
 	id      string
+	alias   bool                     // 经别名路由到主邮箱（address 为别名地址）
+	list    *domain.DistributionList // 非空时投递到列表的每个成员邮箱
+	sink    *sinkTarget              // 非空时不投递，只累计域名的黑洞统计
+}
+
+// sinkTarget 黑洞模式收件人（设置在 RCPT 时取快照，同一封邮件中途关闭不影响已接收的收件人）
+type sinkTarget struct {
+	domain   string
+	settings domain.SinkSettings
 }
 
 // Mail 处理 MAIL 命令。
+//
+// 维护模式和关闭前排空期间返回 421 临时错误，发件方会排队稍后重试而不是退信。
 func (s *session) Mail(from string, opts *gosmtp.MailOptions) error {
+	if s.backend.draining.Load() {
+		return errShuttingDown
+	}
+	s.tracked.setState(SessionStateMail)
+	if s.backend.maintenance != nil {
+		if readOnly, message := s.backend.maintenance.MaintenanceState(); readOnly {
+			if message == "" {
+				message = "service temporarily unavailable for maintenance, try again later"
+			}
+			return &gosmtp.SMTPError{
+				Code:         421,
+				EnhancedCode: gosmtp.EnhancedCode{4, 3, 2},
+				Message:      message,
+			}
+		}
+	}
+
+	if s.backend.guard != nil && !s.backend.guard.allowSender(from) {
+		return s.backend.reject(RejectReasonSenderRate, errSenderRateLimited)
+	}
+	// 协议层按兜底上限检查 SIZE 参数，这里按当前配置的上限提前拒绝
+	if opts != nil && opts.Size > s.backend.maxMessageBytes.Load() {
+		return gosmtp.ErrDataTooLarge
+	}
+
 	s.fromAddress = from
+	s.tracked.setMailFrom(from)
 	return nil
 }
 
@@ -108,9 +408,17 @@ func (s *session) Mail(from string, opts *gosmtp.MailOptions) error {
 // 验证流程：
 // 1. 提取收件人域名
 // 2. 检查域名是否在激活的系统域名列表或用户域名列表中
-// 3. 查找对应的邮箱或别名
-// 4. 如果都不存在，返回 550 错误
+// 3. 域名开启黑洞模式时直接接收（不查找邮箱）
+// 4. 依次查找对应的邮箱、别名、分发列表
+// 5. 通配模式的用户域名投递到通配邮箱（或自动创建邮箱）
+// 6. 如果都不存在，返回 550 错误
+//
+// 已接收的收件人达到单次事务上限后，后续收件人返回 452（不再查找），
+// 发件方会在新的事务中重试，单封 DATA 触发的工作量因此有上限。
 func (s *session) Rcpt(to string, _ *gosmtp.RcptOptions) error {
+	s.tracked.setState(SessionStateRcpt)
+	defer func() { s.tracked.setRecipients(len(s.recipients)) }()
+
 	addr := normalizeAddress(to)
 
 	// 提取域名部分
@@ -124,49 +432,67 @@ func (s *session) Rcpt(to string, _ *gosmtp.RcptOptions) error {
 	}
 	recipientDomain := parts[1]
 
-	// 验证域名是否被管理
-	domainAllowed := false
-
-	// 检查系统域名
-	if s.backend.systemDomains != nil {
-		activeDomains, err := s.backend.systemDomains.GetAllActiveDomains()
-		if err == nil {
-			for _, d := range activeDomains {
-				if strings.EqualFold(d, recipientDomain) {
-					domainAllowed = true
-					break
-				}
-			}
+	if len(s.recipients) >= s.backend.maxRecipients {
+		if s.backend.metrics != nil {
+			s.backend.metrics.RecordRecipientDeferred()
 		}
+		return errTooManyRecipients
 	}
 
-	// 如果不在系统域名中，检查用户域名
-	if !domainAllowed && s.backend.userDomainService != nil {
-		userDomains, err := s.backend.userDomainService.ListUserDomains("")
-		if err == nil {
-			for _, ud := range userDomains {
-				if ud.IsActive && ud.Status == domain.DomainStatusVerified {
-					if strings.EqualFold(ud.Domain, recipientDomain) {
-						domainAllowed = true
-						break
-					}
-				}
-			}
+	// 验证域名是否被管理（系统域名或已验证的用户域名）
+	// 过了宽限期的用户域名单独提示，便于发件方定位原因
+	domainAllowed := false
+	var res *service.DomainResolution
+	if s.backend.systemDomains != nil {
+		res = s.backend.systemDomains.ResolveDomain(s.context(), recipientDomain)
+		if res.Lapsed() {
+			return s.backend.reject(RejectReasonRelayDenied, errDomainExpired)
 		}
+		domainAllowed = res.Managed()
 	}
 
 	// 域名不在管理列表中，拒绝接收
 	if !domainAllowed {
-		return &gosmtp.SMTPError{
-			Code:         550,
-			EnhancedCode: gosmtp.EnhancedCode{5, 7, 1},
-			Message:      "relay access denied - domain not managed by this server",
+		return s.backend.reject(RejectReasonRelayDenied, errRelayDenied)
+	}
+
+	// 黑洞模式：接收任意收件人，不查找也不创建邮箱
+	if s.backend.sinks != nil {
+		if settings := res.Sink(); settings != nil {
+			s.recipients = append(s.recipients, recipient{
+				address: addr,
+				sink:    &sinkTarget{domain: res.Domain, settings: *settings},
+			})
+			return nil
+		}
+	}
+
+	// 灰名单：首次出现的（发件网段、发件地址、收件地址）先返回 451，发件方重试后放行
+	if s.backend.guard != nil && !s.backend.guard.allowTriple(s.remoteIP, s.fromAddress, addr) {
+		return s.backend.reject(RejectReasonGreylisted, errGreylisted)
+	}
+
+	// 白名单模式：不在白名单中的前缀一律拒收（包括移出白名单前已创建的邮箱）
+	if s.backend.whitelist != nil && res.Whitelisted() {
+		allowed, err := s.backend.whitelist.Allows(s.context(), res, parts[0])
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return &gosmtp.SMTPError{
+				Code:         550,
+				EnhancedCode: gosmtp.EnhancedCode{5, 1, 1},
+				Message:      "recipient not permitted on this domain",
+			}
 		}
 	}
 
 	// 首先尝试查找主邮箱
-	mb, err := s.backend.mailboxes.GetByAddress(addr)
+	mb, err := s.backend.mailboxes.GetByAddress(s.context(), addr)
 	if err == nil {
+		if err := s.checkDeliverable(mb); err != nil {
+			return err
+		}
 		// 找到主邮箱
 		s.recipients = append(s.recipients, recipient{
 			address: addr,
@@ -177,41 +503,228 @@ func (s *session) Rcpt(to string, _ *gosmtp.RcptOptions) error {
 
 	// 如果没有找到主邮箱，尝试查找别名
 	if s.backend.aliases != nil {
-		alias, err := s.backend.aliases.GetByAddress(addr)
+		alias, err := s.backend.aliases.GetByAddress(s.context(), addr)
 		if err == nil && alias.IsActive {
+			if target, err := s.backend.mailboxes.Get(s.context(), alias.MailboxID); err == nil {
+				if err := s.checkDeliverable(target); err != nil {
+					return err
+				}
+			}
 			// 找到激活的别名，将邮件路由到主邮箱
 			s.recipients = append(s.recipients, recipient{
 				address: addr,            // 保留原始收件地址
 				id:      alias.MailboxID, // 使用别名关联的主邮箱ID
+				alias:   true,
+			})
+			return nil
+		}
+	}
+
+	// 最后尝试分发列表（邮件复制到每个成员邮箱）
+	if s.backend.lists != nil {
+		if list, ok := s.backend.lists.ResolveAddress(s.context(), addr); ok {
+			s.recipients = append(s.recipients, recipient{
+				address: addr,
+				list:    list,
 			})
 			return nil
 		}
 	}
 
+	// 通配收件：投递到通配邮箱，或为域名所有者自动创建邮箱
+	if s.backend.catchAll != nil && res.CatchAll() != nil {
+		target, err := s.backend.catchAll.Route(s.context(), res, addr)
+		if err == nil {
+			if err := s.checkDeliverable(target); err != nil {
+				return err
+			}
+			s.recipients = append(s.recipients, recipient{
+				address: addr,
+				id:      target.ID,
+				alias:   target.Address != addr, // 投递到通配邮箱时保留原始收件地址
+			})
+			return nil
+		}
+		if !errors.Is(err, service.ErrCatchAllUnavailable) {
+			return err
+		}
+	}
+
 	// 域名是管理的，但邮箱不存在
 	// 返回 550 错误，拒绝接收发往不存在邮箱的邮件
-	return &gosmtp.SMTPError{
-		Code:         550,
-		EnhancedCode: gosmtp.EnhancedCode{5, 1, 1},
-		Message:      "recipient mailbox not found",
+	return s.backend.reject(RejectReasonUnknownRecipient, errRecipientNotFound)
+}
+
+// checkDeliverable 检查邮箱能否接收：停用的邮箱返回 550，邮件数量已达等级上限返回 552
+func (s *session) checkDeliverable(mailbox *domain.Mailbox) error {
+	if mailbox.Suspended {
+		return errMailboxSuspended
 	}
+	if errors.Is(s.backend.mailboxes.CheckMessageQuota(s.context(), mailbox), service.ErrMailboxFull) {
+		return errMailboxFull
+	}
+	return nil
 }
 
 // Data 处理邮件内容。
+//
+// 邮件边读边解析：原始内容经大小限制后同时写入临时文件（spool）和 MIME 解析器，
+// 大附件直接流式写入 blob 存储，内存中不保留整封邮件。整封邮件只解析一次，
+// 多个收件人共享同一份临时文件和附件 blob。超出大小限制或解析失败时清理临时文件和暂存附件。
+//
+// 启用垃圾邮件评分时，原始邮件同时流式提交给评分服务，分数在投递前按各收件邮箱的阈值处理：
+// 超过硬阈值的收件人不投递（全部超过时返回 550），超过软阈值的投递到隔离区。
+//
+// 整个处理过程记为会话 span 下的 smtp.data 子 span，其间的存储调用挂在它下面。
 func (s *session) Data(r io.Reader) error {
-	rawBytes, err := io.ReadAll(io.LimitReader(r, 10<<20)) // 10MB
+	parent := s.ctx
+	ctx, span := tracing.Start(s.context(), "smtp.data", trace.WithAttributes(attribute.Int("smtp.recipients", len(s.recipients))))
+	s.ctx = ctx
+	err := s.data(r)
+	s.ctx = parent
+	tracing.End(span, err)
+	return err
+}
+
+// data 处理邮件内容（见 Data）
+func (s *session) data(r io.Reader) error {
+	s.tracked.setState(SessionStateData)
+	limited := &limitedReader{r: r, remaining: s.backend.maxMessageBytes.Load(), tracked: s.tracked}
+
+	var stager BlobStager
+	var spool *filesystem.Spool
+	var memRaw bytes.Buffer
+	var rawSink io.Writer
+	if s.backend.fsStore != nil {
+		var err error
+		spool, err = s.backend.fsStore.CreateSpool()
+		if err != nil {
+			return err
+		}
+		// 各收件人已链接或复制原始邮件，临时文件随后删除
+		defer spool.Discard()
+		stager = s.backend.fsStore
+		rawSink = spool
+	} else {
+		// 没有文件系统存储时只能在内存中保留原始邮件
+		rawSink = &memRaw
+	}
+
+	var scoring *spamScoring
+	if s.backend.spamFilter != nil {
+		scoring = startSpamScoring(s.backend.spamFilter, s.spamEnvelope())
+		defer scoring.abort()
+		rawSink = io.MultiWriter(rawSink, scoring)
+	}
+	var dkimCheck *dkimVerification
+	if s.backend.dkimVerifier != nil {
+		dkimCheck = startDKIMVerification(s.context(), s.backend.dkimVerifier)
+		defer dkimCheck.abort()
+		rawSink = io.MultiWriter(rawSink, dkimCheck)
+	}
+	body := io.TeeReader(limited, rawSink)
+
+	parsed, err := ParseEmailStream(body, stager)
+	if err == nil {
+		// 解析器不一定读到结尾，补读剩余内容保证原始邮件完整
+		if _, err = io.Copy(io.Discard, body); err != nil {
+			parsed.releaseStaged(stager)
+		}
+	}
+	if limited.err != nil {
+		// 超出大小限制或连接中断，优先返回底层错误（超限时为 552）
+		return limited.err
+	}
+	if err != nil {
+		return fmt.Errorf("parse email: %w", err)
+	}
+	defer parsed.releaseStaged(stager)
+	if s.backend.stableIDs {
+		parsed.stabilizeAttachmentIDs()
+	}
+
+	rawInput := service.CreateMessageInput{}
+	if spool != nil {
+		if err := spool.Close(); err != nil {
+			return err
+		}
+		rawInput.RawFile = spool.Path()
+		rawInput.RawSize = spool.Size()
+	} else {
+		rawInput.Raw = memRaw.String()
+	}
+
+	if s.backend.metrics != nil {
+		s.backend.metrics.RecordRecipientsPerTransaction(len(s.recipients))
+	}
+
+	// 一次批量查询所有直投邮箱：RCPT 之后被删除或过期的邮箱不再投递，邮箱阈值也从这里取
+	mailboxes, err := s.lookupMailboxes()
 	if err != nil {
 		return err
 	}
 
-	// 使用新的 MIME 解析器
-	parsed, err := ParseEmail(rawBytes)
+	var spamResult *spam.Result
+	verdicts := make([]spam.Verdict, len(s.recipients))
+	if scoring != nil {
+		if spamResult, err = scoring.finish(); err != nil {
+			return errSpamUnavailable
+		}
+		rejected := 0
+		owners := make(map[string]*domain.User)
+		for i, rcpt := range s.recipients {
+			if verdicts[i] = s.spamVerdict(rcpt, mailboxes[rcpt.address], spamResult, owners); verdicts[i] == spam.VerdictReject {
+				rejected++
+			}
+		}
+		if len(s.recipients) > 0 && rejected == len(s.recipients) {
+			return s.backend.reject(RejectReasonSpam, errSpamRejected)
+		}
+	}
+
+	rawSize := rawInput.RawSize
+	if spool == nil {
+		rawSize = int64(len(rawInput.Raw))
+	}
+	sunk := make(map[string]bool)
+
+	captured := s.backend.captureHeaders(parsed.Header)
+	var verification dkim.Verification
+	if dkimCheck != nil {
+		verification = dkimCheck.finish()
+	}
+	authResults := s.authenticate(parsed.Header, verification)
+	if authResults.Failed() && authResults.Action == domain.AuthActionReject {
+		return s.backend.reject(RejectReasonAuth, errAuthRejected)
+	}
+	infected, err := s.scanAttachments(parsed.Attachments)
 	if err != nil {
-		return fmt.Errorf("parse email: %w", err)
+		return err
 	}
 
-	// 为每个收件人创建邮件
-	for _, rcpt := range s.recipients {
+	// 直投邮箱（含别名）的邮件在循环后一次批量入库，分发列表和黑洞模式单独处理
+	var batch []service.CreateMessageInput
+	var notify []*domain.Message
+	var accepted []string // 接收的收件地址（收信流量统计）
+
+	// 为每个收件人创建邮件（共享解析结果，只复制附件引用）
+	for i, rcpt := range s.recipients {
+		// 超过该收件人硬阈值：不投递（DATA 只能返回一个状态，其余收件人照常投递）
+		if verdicts[i] == spam.VerdictReject {
+			continue
+		}
+		accepted = append(accepted, rcpt.address)
+		// 邮箱在 RCPT 之后被删除或过期
+		if mailboxes != nil && rcpt.list == nil && rcpt.sink == nil && !rcpt.alias && mailboxes[rcpt.address] == nil {
+			continue
+		}
+		// 邮箱在 RCPT 之后被停用
+		if mailbox := mailboxes[rcpt.address]; mailbox != nil && mailbox.Suspended {
+			continue
+		}
+		quarantined := verdicts[i] == spam.VerdictQuarantine || infected ||
+			(authResults.Failed() && authResults.Action == domain.AuthActionQuarantine)
+
 		// 1️⃣ 创建邮件元数据（不包含 Raw、Text、HTML - 这些存文件）
 		messageInput := service.CreateMessageInput{
 			MailboxID: rcpt.id,
@@ -220,9 +733,26 @@ func (s *session) Data(r io.Reader) error {
 			Subject:   parsed.Subject,
 			Text:      parsed.Text,
 			HTML:      parsed.HTML,
-			Raw:       string(rawBytes),
+			Raw:       rawInput.Raw,
+			RawFile:   rawInput.RawFile,
+			RawSize:   rawInput.RawSize,
 			IsRead:    false,
+
+			DKIMResult:      verification.Result,
+			DKIMDomain:      verification.Domain,
+			AuthResults:     authResults,
+			CapturedHeaders: captured,
+		}
+		if mailbox := mailboxes[rcpt.address]; mailbox != nil {
+			messageInput.ExpiryRules = mailbox.ExpiryRules
+		}
+		if spamResult != nil {
+			messageInput.SpamScore = spamResult.Score
+			messageInput.SpamAction = spamResult.Action
+			messageInput.SpamSymbols = spamResult.Symbols
 		}
+		messageInput.IsSpam = verdicts[i] == spam.VerdictQuarantine
+		messageInput.Quarantined = quarantined
 
 		for _, att := range parsed.Attachments {
 			messageInput.Attachments = append(messageInput.Attachments, &domain.Attachment{
@@ -230,24 +760,178 @@ func (s *session) Data(r io.Reader) error {
 				Filename:    att.Filename,
 				ContentType: att.ContentType,
 				Size:        att.Size,
+				SHA256:      att.SHA256,
 				Content:     att.Content,
+
+				DetectedContentType: att.DetectedContentType,
+				ContentTypeMismatch: att.ContentTypeMismatch,
+				Infected:            att.Infected,
+				Threat:              att.Threat,
 			})
 		}
 
-		message, err := s.backend.messages.Create(messageInput)
-		if err != nil {
-			return err
+		// 黑洞模式：同一域名的多个收件人只计一封，按抽样率投递到诊断邮箱
+		if rcpt.sink != nil {
+			if sunk[rcpt.sink.domain] {
+				continue
+			}
+			sunk[rcpt.sink.domain] = true
+			if err := s.deliverSink(rcpt.sink, messageInput, rawSize); err != nil {
+				return err
+			}
+			continue
 		}
 
-		// 4️⃣ WebSocket 通知（使用元数据）
-		if s.backend.wsHub != nil {
-			s.backend.wsHub.NotifyNewMail(rcpt.id, message)
+		// 分发列表：每个成员各自入库和通知，单个成员失败记入投递报告，不中断其余成员
+		if rcpt.list != nil {
+			_, messages := s.backend.lists.Deliver(s.context(), rcpt.list, messageInput)
+			for _, message := range messages {
+				if s.backend.ingest != nil {
+					s.backend.ingest.RecordIngest(nil)
+				}
+				if !quarantined {
+					notify = append(notify, message)
+				}
+			}
+			continue
+		}
+
+		batch = append(batch, messageInput)
+	}
+
+	// 2️⃣ 批量入库（数据库实现为同一事务，任一失败时全部回滚，发件方整体重试）
+	messages, err := s.backend.messages.CreateBatch(s.context(), batch)
+	if s.backend.ingest != nil {
+		for range batch {
+			s.backend.ingest.RecordIngest(err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// 3️⃣ WebSocket 通知（使用元数据，隔离区邮件不通知）
+	for _, message := range messages {
+		if !message.Quarantined {
+			notify = append(notify, message)
+		}
+	}
+	s.notifyNewMail(notify)
+
+	// 4️⃣ 转发到外部地址（投递记录入队后异步发出，失败由重试任务继续）
+	if s.backend.forwards != nil {
+		s.backend.forwards.Enqueue(s.context(), notify)
+	}
+
+	if s.backend.mailFlow != nil {
+		s.backend.mailFlow.RecordMessage(s.fromAddress, accepted)
+	}
+	return nil
+}
+
+// lookupMailboxes 批量查询直投收件人的邮箱，返回地址到邮箱的映射
+//
+// 未配置邮箱服务时返回 nil（不做二次校验）。别名和分发列表收件人不在结果中。
+func (s *session) lookupMailboxes() (map[string]*domain.Mailbox, error) {
+	if s.backend.mailboxes == nil {
+		return nil, nil
+	}
+	addresses := make([]string, 0, len(s.recipients))
+	for _, rcpt := range s.recipients {
+		if rcpt.list == nil && rcpt.sink == nil && !rcpt.alias {
+			addresses = append(addresses, rcpt.address)
+		}
+	}
+	return s.backend.mailboxes.GetByAddresses(s.context(), addresses)
+}
+
+// notifyNewMail 异步推送新邮件通知，不占用 SMTP 会话（启用发件箱时由分发器推送）
+func (s *session) notifyNewMail(messages []*domain.Message) {
+	if s.backend.wsHub == nil || s.backend.messages.OutboxEnabled() || len(messages) == 0 {
+		return
+	}
+	hub := s.backend.wsHub
+	go func() {
+		for _, message := range messages {
+			hub.NotifyNewMail(s.context(), message.MailboxID, message)
 		}
+	}()
+}
+
+// deliverSink 累计黑洞统计，命中抽样时投递一份到诊断邮箱
+//
+// 抽样投递失败不影响接收结果（统计已计入），只上报入库错误。
+func (s *session) deliverSink(target *sinkTarget, input service.CreateMessageInput, size int64) error {
+	mailboxID, err := s.backend.sinks.Record(s.context(), target.domain, target.settings, s.fromAddress, size)
+	if s.backend.ingest != nil {
+		s.backend.ingest.RecordIngest(err)
+	}
+	if err != nil || mailboxID == "" {
+		return err
 	}
 
+	input.MailboxID = mailboxID
+	message, err := s.backend.messages.Create(s.context(), input)
+	if s.backend.ingest != nil {
+		s.backend.ingest.RecordIngest(err)
+	}
+	if err != nil {
+		return nil
+	}
+	_ = s.backend.sinks.RecordSample(s.context(), target.domain)
+	if !input.Quarantined {
+		s.notifyNewMail([]*domain.Message{message})
+	}
 	return nil
 }
 
+// spamEnvelope 提交给评分服务的信封信息
+func (s *session) spamEnvelope() spam.Envelope {
+	env := spam.Envelope{From: s.fromAddress}
+	for _, rcpt := range s.recipients {
+		env.Recipients = append(env.Recipients, rcpt.address)
+	}
+	return env
+}
+
+// limitedReader 限制邮件大小：超出上限时返回 552，而不是静默截断
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	err       error           // 第一个非 EOF 错误（超限或底层读取失败）
+	tracked   *trackedSession // 累计会话读取的字节数（可为空）
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if l.remaining <= 0 {
+		// 已到上限，再探测一个字节判断是否还有数据
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			l.err = gosmtp.ErrDataTooLarge
+			return 0, l.err
+		}
+		if err != nil && err != io.EOF {
+			l.err = err
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	l.tracked.addBytes(n)
+	if err != nil && err != io.EOF {
+		l.err = err
+	}
+	return n, err
+}
+
 // AuthPlain 处理 PLAIN 认证（此处允许匿名）。
 func (s *session) AuthPlain(username, password string) error {
 	return nil
@@ -257,13 +941,32 @@ func (s *session) AuthPlain(username, password string) error {
 func (s *session) Reset() {
 	s.fromAddress = ""
 	s.recipients = nil
+	s.tracked.setState(SessionStateConnected)
 }
 
-// Logout 会话结束。
+// Logout 会话结束（连接关闭时总会调用），移除活跃会话登记并取消会话上下文。
 func (s *session) Logout() error {
+	s.tracked.close()
+	if s.backend.guard != nil && s.remoteIP != nil {
+		s.backend.guard.releaseConn(s.remoteIP)
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if s.span != nil {
+		s.span.End()
+	}
 	return nil
 }
 
+// context 会话上下文，存储调用都在它之下进行
+func (s *session) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
 func normalizeAddress(addr string) string {
 	addr = strings.TrimSpace(addr)
 	addr = strings.Trim(addr, "<>")
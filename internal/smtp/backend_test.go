@@ -0,0 +1,1360 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/dkim"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/mailauth"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/spam"
+	"tempmail/backend/internal/storage/filesystem"
+	"tempmail/backend/internal/storage/memory"
+)
+
+type ingestFixture struct {
+	dir      string
+	store    *memory.Store
+	fs       *filesystem.Store
+	messages *service.MessageService
+	backend  *Backend
+}
+
+func newIngestFixture(t testing.TB, mailboxIDs ...string) *ingestFixture {
+	t.Helper()
+	dir := t.TempDir()
+	store := memory.NewStore(24 * time.Hour)
+	fs, err := filesystem.NewStore(dir)
+	require.NoError(t, err)
+
+	messages := service.NewMessageService(store)
+	messages.SetFilesystemStore(fs)
+	for _, id := range mailboxIDs {
+		require.NoError(t, store.SaveMailbox(context.Background(), &domain.Mailbox{
+			ID: id, Address: id + "@corp.example", LocalPart: id, Domain: "corp.example",
+			Token: "tok-" + id, CreatedAt: time.Now(),
+		}))
+	}
+
+	backend := NewBackend(nil, messages, nil, nil, nil, nil, fs)
+	return &ingestFixture{dir: dir, store: store, fs: fs, messages: messages, backend: backend}
+}
+
+func (f *ingestFixture) session(mailboxIDs ...string) *session {
+	sess := &session{backend: f.backend, fromAddress: "sender@example.com"}
+	for _, id := range mailboxIDs {
+		sess.recipients = append(sess.recipients, recipient{address: id + "@corp.example", id: id})
+	}
+	return sess
+}
+
+// tempFiles 列出入库临时目录中的文件
+func (f *ingestFixture) tempFiles(t testing.TB) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(f.dir, "tmp"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// blobFiles 列出 blob 存储中的内容文件
+func (f *ingestFixture) blobFiles(t testing.TB) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(f.dir, "blobs", "*", "*", "*"))
+	require.NoError(t, err)
+	var files []string
+	for _, match := range matches {
+		if !strings.HasSuffix(match, ".refs") {
+			files = append(files, match)
+		}
+	}
+	return files
+}
+
+// buildMessage 生成带 base64 附件的 multipart 邮件；truncate 为 true 时缺少结束边界
+func buildMessage(attachments map[string][]byte, truncate bool) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: sender@example.com\r\nTo: rcpt@corp.example\r\nSubject: streaming\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=\"b1\"\r\n\r\n")
+	buf.WriteString("--b1\r\nContent-Type: text/plain; charset=utf-8\r\n\r\nhello body\r\n")
+	for name, content := range attachments {
+		fmt.Fprintf(&buf, "--b1\r\nContent-Type: application/octet-stream\r\nContent-Disposition: attachment; filename=%q\r\nContent-Transfer-Encoding: base64\r\n\r\n", name)
+		encoded := base64.StdEncoding.EncodeToString(content)
+		for len(encoded) > 76 {
+			buf.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		buf.WriteString(encoded + "\r\n")
+	}
+	if !truncate {
+		buf.WriteString("--b1--\r\n")
+	}
+	return buf.Bytes()
+}
+
+func randomBytes(t testing.TB, n int) []byte {
+	t.Helper()
+	data := make([]byte, n)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+	return data
+}
+
+func TestSessionData_Streaming(t *testing.T) {
+	t.Run("超出大小限制时返回552并删除临时文件", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		f.backend.SetMaxMessageBytes(512 << 10)
+		raw := buildMessage(map[string][]byte{"big.bin": randomBytes(t, 1<<20)}, false)
+
+		err := f.session("mb-1").Data(bytes.NewReader(raw))
+		var smtpErr *gosmtp.SMTPError
+		require.True(t, errors.As(err, &smtpErr), "expected SMTP error, got %v", err)
+		assert.Equal(t, 552, smtpErr.Code)
+
+		assert.Empty(t, f.tempFiles(t))
+		assert.Empty(t, f.blobFiles(t))
+		messages, err := f.store.ListMessages(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+	})
+
+	t.Run("部分写入后解析失败时清理临时文件和暂存附件", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		raw := buildMessage(map[string][]byte{"big.bin": randomBytes(t, 1<<20)}, true)
+
+		err := f.session("mb-1").Data(bytes.NewReader(raw))
+		require.Error(t, err)
+
+		assert.Empty(t, f.tempFiles(t))
+		assert.Empty(t, f.blobFiles(t))
+		messages, err := f.store.ListMessages(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+	})
+
+	t.Run("流式存储的附件内容正确且多收件人共享", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1", "mb-2")
+		big := randomBytes(t, 1<<20)
+		small := []byte("small attachment")
+		raw := buildMessage(map[string][]byte{"big.bin": big, "small.txt": small}, false)
+
+		require.NoError(t, f.session("mb-1", "mb-2").Data(bytes.NewReader(raw)))
+		assert.Empty(t, f.tempFiles(t))
+
+		bigHash := sha256.Sum256(big)
+		for _, mailboxID := range []string{"mb-1", "mb-2"} {
+			messages, err := f.store.ListMessages(t.Context(), mailboxID)
+			require.NoError(t, err)
+			require.Len(t, messages, 1)
+			msg := messages[0]
+			assert.Equal(t, int64(len(raw)), msg.Size)
+
+			stored, err := f.fs.GetMessageRaw(mailboxID, msg.ID)
+			require.NoError(t, err)
+			assert.Equal(t, raw, stored)
+
+			contents := map[string][]byte{}
+			for _, att := range msg.Attachments {
+				full, err := f.messages.GetAttachment(t.Context(), mailboxID, msg.ID, att.ID)
+				require.NoError(t, err)
+				reader, err := full.Open()
+				require.NoError(t, err)
+				data, err := io.ReadAll(reader)
+				reader.Close()
+				require.NoError(t, err)
+				assert.Equal(t, full.Size, int64(len(data)))
+				contents[full.Filename] = data
+			}
+			assert.Equal(t, big, contents["big.bin"])
+			assert.Equal(t, small, contents["small.txt"])
+		}
+
+		// 两封邮件共享一个 blob，暂存引用已释放
+		refs, err := os.ReadDir(filepath.Join(f.dir, "blobs", hex.EncodeToString(bigHash[:1]), hex.EncodeToString(bigHash[1:2]), hex.EncodeToString(bigHash[:])+".refs"))
+		require.NoError(t, err)
+		assert.Len(t, refs, 2)
+		for _, ref := range refs {
+			assert.False(t, strings.HasPrefix(ref.Name(), "staging_"))
+		}
+	})
+}
+
+// peakHeap 采样记录运行期间的堆内存峰值
+type peakHeap struct {
+	peak atomic.Uint64
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func startPeakHeap() *peakHeap {
+	runtime.GC()
+	p := &peakHeap{stop: make(chan struct{})}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		var ms runtime.MemStats
+		for {
+			runtime.ReadMemStats(&ms)
+			if ms.HeapInuse > p.peak.Load() {
+				p.peak.Store(ms.HeapInuse)
+			}
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+	return p
+}
+
+func (p *peakHeap) Stop() uint64 {
+	close(p.stop)
+	p.wg.Wait()
+	return p.peak.Load()
+}
+
+func TestSessionRcpt_ExpiredUserDomain(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{
+		ID: "ud-1", UserID: "alice", Domain: "owned.example", Mode: domain.DomainModeShared,
+		Status: domain.DomainStatusVerified, IsActive: true, ExpiresAt: &expiresAt, CreatedAt: time.Now(),
+	}))
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "inbox@owned.example", LocalPart: "inbox", Domain: "owned.example",
+		Token: "tok", CreatedAt: time.Now(),
+	}))
+
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"owned.example"}}}
+	backend := NewBackend(service.NewMailboxService(store, store, cfg), service.NewMessageService(store), nil,
+		service.NewSystemDomainService(store, cfg), nil, nil, nil)
+
+	var now time.Time
+	previous := service.CurrentDomainPolicy()
+	service.SetDomainPolicy(service.DomainPolicy{GracePeriod: 14 * 24 * time.Hour, Now: func() time.Time { return now }})
+	t.Cleanup(func() { service.SetDomainPolicy(previous) })
+
+	rcpt := func() error {
+		sess := &session{backend: backend, fromAddress: "sender@example.com"}
+		return sess.Rcpt("inbox@owned.example", nil)
+	}
+
+	t.Run("宽限期内照常收信", func(t *testing.T) {
+		now = expiresAt.Add(13 * 24 * time.Hour)
+		assert.NoError(t, rcpt())
+	})
+
+	t.Run("宽限期结束后返回 550", func(t *testing.T) {
+		now = expiresAt.Add(14*24*time.Hour + time.Minute)
+		var smtpErr *gosmtp.SMTPError
+		require.ErrorAs(t, rcpt(), &smtpErr)
+		assert.Equal(t, 550, smtpErr.Code)
+		assert.Contains(t, smtpErr.Message, "domain expired")
+	})
+}
+
+func TestSessionRcpt_SuspendedMailbox(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "scam@temp.example", LocalPart: "scam", Domain: "temp.example",
+		Token: "tok", Suspended: true, CreatedAt: time.Now(),
+	}))
+	require.NoError(t, store.SaveSystemDomain(t.Context(), &domain.SystemDomain{
+		ID: "sd-1", Domain: "temp.example", Status: domain.SystemDomainStatusVerified,
+		IsActive: true, CreatedAt: time.Now(),
+	}))
+	require.NoError(t, store.SaveAlias(t.Context(), &domain.MailboxAlias{
+		ID: "alias-1", MailboxID: "mb-1", Address: "offers@temp.example", IsActive: true,
+	}))
+
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.example"}}}
+	backend := NewBackend(service.NewMailboxService(store, store, cfg), service.NewMessageService(store),
+		service.NewAliasService(store, store, cfg), service.NewSystemDomainService(store, cfg), nil, nil, nil)
+
+	for _, addr := range []string{"scam@temp.example", "offers@temp.example"} {
+		t.Run(addr, func(t *testing.T) {
+			sess := &session{backend: backend, fromAddress: "sender@example.com"}
+			var smtpErr *gosmtp.SMTPError
+			require.ErrorAs(t, sess.Rcpt(addr, nil), &smtpErr)
+			assert.Equal(t, 550, smtpErr.Code)
+			assert.Equal(t, "mailbox suspended", smtpErr.Message)
+			assert.Empty(t, sess.recipients)
+		})
+	}
+}
+
+func TestSessionRcpt_MailboxFull(t *testing.T) {
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: "user-1", Email: "u@example.com", Username: "u", Tier: domain.TierFree}))
+	userID := "user-1"
+	limit := domain.DefaultQuotas(domain.TierFree).MaxMessagesPerMailbox
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "full@temp.example", LocalPart: "full", Domain: "temp.example",
+		Token: "tok", UserID: &userID, TotalCount: limit, CreatedAt: time.Now(),
+	}))
+	require.NoError(t, store.SaveSystemDomain(t.Context(), &domain.SystemDomain{
+		ID: "sd-1", Domain: "temp.example", Status: domain.SystemDomainStatusVerified,
+		IsActive: true, CreatedAt: time.Now(),
+	}))
+	require.NoError(t, store.SaveAlias(t.Context(), &domain.MailboxAlias{
+		ID: "alias-1", MailboxID: "mb-1", Address: "alias@temp.example", IsActive: true,
+	}))
+
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.example"}}}
+	backend := NewBackend(service.NewMailboxService(store, store, cfg), service.NewMessageService(store),
+		service.NewAliasService(store, store, cfg), service.NewSystemDomainService(store, cfg), nil, nil, nil)
+
+	for _, addr := range []string{"full@temp.example", "alias@temp.example"} {
+		t.Run(addr, func(t *testing.T) {
+			sess := &session{backend: backend, fromAddress: "sender@example.com"}
+			var smtpErr *gosmtp.SMTPError
+			require.ErrorAs(t, sess.Rcpt(addr, nil), &smtpErr)
+			assert.Equal(t, 552, smtpErr.Code)
+			assert.Equal(t, gosmtp.EnhancedCode{5, 2, 2}, smtpErr.EnhancedCode)
+			assert.Empty(t, sess.recipients)
+		})
+	}
+
+	t.Run("升级等级后恢复收信", func(t *testing.T) {
+		user, err := store.GetUserByID(t.Context(), "user-1")
+		require.NoError(t, err)
+		user.Tier = domain.TierBasic
+		require.NoError(t, store.UpdateUser(t.Context(), user))
+
+		sess := &session{backend: backend, fromAddress: "sender@example.com"}
+		assert.NoError(t, sess.Rcpt("full@temp.example", nil))
+	})
+}
+
+// BenchmarkIngest10MB 对比整封缓冲与流式入库的内存峰值（peak-heap-bytes 指标）
+func BenchmarkIngest10MB(b *testing.B) {
+	raw := buildMessage(map[string][]byte{"big.bin": randomBytes(b, 7<<20)}, false)
+
+	b.Run("buffered", func(b *testing.B) {
+		peak := startPeakHeap()
+		for i := 0; i < b.N; i++ {
+			data, err := io.ReadAll(bytes.NewReader(raw))
+			require.NoError(b, err)
+			parsed, err := ParseEmail(data)
+			require.NoError(b, err)
+			_ = string(data)
+			_ = parsed
+		}
+		b.ReportMetric(float64(peak.Stop()), "peak-heap-bytes")
+	})
+
+	b.Run("streaming", func(b *testing.B) {
+		f := newIngestFixture(b, "mb-1")
+		peak := startPeakHeap()
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, f.session("mb-1").Data(bytes.NewReader(raw)))
+		}
+		b.ReportMetric(float64(peak.Stop()), "peak-heap-bytes")
+	})
+}
+
+// recordingSpamMetrics 记录评分指标
+type recordingSpamMetrics struct {
+	mu       sync.Mutex
+	outcomes []string
+}
+
+func (m *recordingSpamMetrics) RecordSpamCheck(outcome string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outcomes = append(m.outcomes, outcome)
+}
+
+// withSpamFilter 为入库 fixture 启用评分（软阈值 6，硬阈值 15，邮箱上限 50）
+func (f *ingestFixture) withSpamFilter(fake *spam.Fake, timeout time.Duration, failOpen bool) *recordingSpamMetrics {
+	cfg := &config.Config{Spam: config.SpamConfig{
+		Timeout: timeout, FailOpen: failOpen, QuarantineScore: 6, RejectScore: 15, MaxMailboxScore: 50,
+	}}
+	f.backend.mailboxes = service.NewMailboxService(f.store, f.store, cfg)
+	metrics := &recordingSpamMetrics{}
+	filter := spam.NewFilter(fake, cfg.Spam)
+	filter.SetMetrics(metrics)
+	f.backend.SetSpamFilter(filter)
+	return metrics
+}
+
+func TestSessionData_SpamScoring(t *testing.T) {
+	raw := buildMessage(map[string][]byte{"note.txt": []byte("buy now")}, false)
+	scored := func(score float64) *spam.Fake {
+		return &spam.Fake{Result: &spam.Result{Score: score, Action: "add header", Symbols: []string{"BAYES_SPAM", "MISSING_DATE"}}}
+	}
+
+	t.Run("低于软阈值正常投递并附带分数", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		fake := scored(2.5)
+		metrics := f.withSpamFilter(fake, time.Second, true)
+
+		require.NoError(t, f.session("mb-1").Data(bytes.NewReader(raw)))
+		assert.Equal(t, raw, fake.Raw(), "评分服务收到完整的原始邮件")
+
+		messages, err := f.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.Equal(t, 2.5, messages[0].SpamScore)
+		assert.Equal(t, "add header", messages[0].SpamAction)
+		assert.Equal(t, []string{"BAYES_SPAM", "MISSING_DATE"}, messages[0].SpamSymbols)
+		assert.False(t, messages[0].Quarantined)
+		assert.False(t, messages[0].IsSpam)
+		assert.Equal(t, []string{"deliver"}, metrics.outcomes)
+
+		stored, err := f.fs.GetMessageRaw("mb-1", messages[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, raw, stored, "评分与落盘并行，不影响原始邮件")
+	})
+
+	t.Run("超过软阈值投递到隔离区", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		metrics := f.withSpamFilter(scored(8), time.Second, true)
+
+		require.NoError(t, f.session("mb-1").Data(bytes.NewReader(raw)))
+
+		inbox, err := f.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Empty(t, inbox)
+		quarantined, err := f.messages.ListQuarantined(t.Context(), "mb-1")
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1)
+		assert.True(t, quarantined[0].Quarantined)
+		assert.True(t, quarantined[0].IsSpam)
+		assert.Equal(t, 8.0, quarantined[0].SpamScore)
+		assert.Equal(t, []string{"quarantine"}, metrics.outcomes)
+	})
+
+	t.Run("超过硬阈值在DATA阶段拒收", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		metrics := f.withSpamFilter(scored(20), time.Second, true)
+
+		err := f.session("mb-1").Data(bytes.NewReader(raw))
+		var smtpErr *gosmtp.SMTPError
+		require.True(t, errors.As(err, &smtpErr), "expected SMTP error, got %v", err)
+		assert.Equal(t, 550, smtpErr.Code)
+
+		messages, err := f.store.ListMessages(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+		assert.Empty(t, f.tempFiles(t))
+		assert.Equal(t, []string{"reject"}, metrics.outcomes)
+	})
+
+	t.Run("邮箱自定义阈值", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1", "mb-qa")
+		f.withSpamFilter(scored(20), time.Second, true)
+
+		quarantine, reject := 30.0, 45.0
+		_, err := f.backend.mailboxes.UpdateSpamThresholds(t.Context(), "mb-qa", service.UpdateSpamThresholdsInput{QuarantineScore: &quarantine, RejectScore: &reject})
+		require.NoError(t, err)
+
+		tooHigh := 80.0
+		_, err = f.backend.mailboxes.UpdateSpamThresholds(t.Context(), "mb-qa", service.UpdateSpamThresholdsInput{RejectScore: &tooHigh})
+		assert.ErrorIs(t, err, service.ErrSpamThresholdInvalid, "不能超过系统上限")
+		inverted := 50.0
+		_, err = f.backend.mailboxes.UpdateSpamThresholds(t.Context(), "mb-qa", service.UpdateSpamThresholdsInput{QuarantineScore: &inverted})
+		assert.ErrorIs(t, err, service.ErrSpamThresholdInvalid, "软阈值必须低于硬阈值")
+
+		// 普通邮箱拒收，QA 邮箱正常投递
+		require.NoError(t, f.session("mb-1", "mb-qa").Data(bytes.NewReader(raw)))
+		messages, err := f.store.ListMessages(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+		qa, err := f.messages.List(t.Context(), "mb-qa")
+		require.NoError(t, err)
+		require.Len(t, qa, 1)
+		assert.Equal(t, 20.0, qa[0].SpamScore)
+
+		// 清除自定义阈值后恢复系统默认
+		reset := 0.0
+		mailbox, err := f.backend.mailboxes.UpdateSpamThresholds(t.Context(), "mb-qa", service.UpdateSpamThresholdsInput{QuarantineScore: &reset, RejectScore: &reset})
+		require.NoError(t, err)
+		assert.Nil(t, mailbox.SpamQuarantineScore)
+		assert.Nil(t, mailbox.SpamRejectScore)
+	})
+
+	t.Run("用户阈值作用于名下邮箱", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		f.withSpamFilter(scored(20), time.Second, true)
+		f.backend.SetUserLookup(f.store)
+
+		userID := "user-qa"
+		require.NoError(t, f.store.CreateUser(t.Context(), &domain.User{ID: userID, Email: "qa@corp.example"}))
+		require.NoError(t, f.store.SaveMailbox(t.Context(), &domain.Mailbox{
+			ID: "mb-user", Address: "mb-user@corp.example", LocalPart: "mb-user", Domain: "corp.example",
+			UserID: &userID, CreatedAt: time.Now(),
+		}))
+		quarantine, reject := 10.0, 30.0
+		users := service.NewUserSpamService(f.store, &config.Config{Spam: config.SpamConfig{QuarantineScore: 6, RejectScore: 15, MaxMailboxScore: 50}})
+		_, err := users.UpdateThresholds(t.Context(), userID, service.UpdateSpamThresholdsInput{QuarantineScore: &quarantine, RejectScore: &reject})
+		require.NoError(t, err)
+
+		// 普通邮箱拒收，用户邮箱按用户阈值投递到隔离区
+		require.NoError(t, f.session("mb-1", "mb-user").Data(bytes.NewReader(raw)))
+		messages, err := f.store.ListMessages(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+		quarantined, err := f.messages.ListQuarantined(t.Context(), "mb-user")
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1)
+		assert.True(t, quarantined[0].IsSpam)
+	})
+
+	t.Run("评分服务超时", func(t *testing.T) {
+		slow := func() *spam.Fake {
+			fake := scored(20)
+			fake.Delay = time.Second
+			return fake
+		}
+
+		t.Run("fail-open 时照常投递且不附分数", func(t *testing.T) {
+			f := newIngestFixture(t, "mb-1")
+			metrics := f.withSpamFilter(slow(), 20*time.Millisecond, true)
+
+			require.NoError(t, f.session("mb-1").Data(bytes.NewReader(raw)))
+			messages, err := f.messages.List(t.Context(), "mb-1")
+			require.NoError(t, err)
+			require.Len(t, messages, 1)
+			assert.Zero(t, messages[0].SpamScore)
+			assert.Empty(t, messages[0].SpamAction)
+			assert.Equal(t, []string{spam.OutcomeUnavailable}, metrics.outcomes)
+		})
+
+		t.Run("fail-closed 时返回451", func(t *testing.T) {
+			f := newIngestFixture(t, "mb-1")
+			metrics := f.withSpamFilter(slow(), 20*time.Millisecond, false)
+
+			err := f.session("mb-1").Data(bytes.NewReader(raw))
+			var smtpErr *gosmtp.SMTPError
+			require.True(t, errors.As(err, &smtpErr), "expected SMTP error, got %v", err)
+			assert.Equal(t, 451, smtpErr.Code)
+
+			messages, err := f.store.ListMessages(t.Context(), "mb-1")
+			require.NoError(t, err)
+			assert.Empty(t, messages)
+			assert.Equal(t, []string{spam.OutcomeUnavailable}, metrics.outcomes)
+		})
+	})
+}
+
+// withSink 为入库 fixture 添加一个开启黑洞模式的系统域名 sink.example
+func (f *ingestFixture) withSink(t *testing.T, settings domain.SinkSettings) *service.SinkService {
+	t.Helper()
+	settings.Enabled = true
+	require.NoError(t, f.store.SaveSystemDomain(t.Context(), &domain.SystemDomain{
+		ID: "sd-sink", Domain: "sink.example", Status: domain.SystemDomainStatusVerified,
+		IsActive: true, CreatedAt: time.Now(),
+	}))
+
+	cfg := &config.Config{}
+	sinks := service.NewSinkService(f.store)
+	_, err := sinks.ConfigureSystemDomain(t.Context(), "sd-sink", settings)
+	require.NoError(t, err)
+
+	f.backend.mailboxes = service.NewMailboxService(f.store, f.store, cfg)
+	f.backend.systemDomains = service.NewSystemDomainService(f.store, cfg)
+	f.backend.SetSinkService(sinks)
+	return sinks
+}
+
+// deliver 完整走一遍 MAIL/RCPT/DATA
+func (f *ingestFixture) deliver(from string, raw []byte, to ...string) error {
+	sess := &session{backend: f.backend}
+	if err := sess.Mail(from, nil); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := sess.Rcpt(addr, nil); err != nil {
+			return err
+		}
+	}
+	return sess.Data(bytes.NewReader(raw))
+}
+
+func TestSession_CatchAll(t *testing.T) {
+	owner := "user-1"
+	setup := func(t *testing.T, settings domain.CatchAllSettings) *ingestFixture {
+		t.Helper()
+		f := newIngestFixture(t)
+		require.NoError(t, f.store.SaveUserDomain(t.Context(), &domain.UserDomain{
+			ID: "ud-1", UserID: owner, Domain: "catch.example", Mode: domain.DomainModeExclusive,
+			Status: domain.DomainStatusVerified, IsActive: true,
+		}))
+		require.NoError(t, f.store.SaveMailbox(t.Context(), &domain.Mailbox{
+			ID: "mb-inbox", Address: "inbox@catch.example", LocalPart: "inbox", Domain: "catch.example",
+			UserID: &owner, CreatedAt: time.Now(),
+		}))
+
+		cfg := &config.Config{}
+		f.backend.mailboxes = service.NewMailboxService(f.store, f.store, cfg)
+		f.backend.systemDomains = service.NewSystemDomainService(f.store, cfg)
+		catchAll := service.NewCatchAllService(f.store, f.backend.mailboxes)
+		_, err := catchAll.ConfigureUserDomain(t.Context(), "ud-1", owner, settings)
+		require.NoError(t, err)
+		f.backend.SetCatchAllService(catchAll)
+		return f
+	}
+
+	t.Run("投递到通配邮箱并保留收件地址", func(t *testing.T) {
+		f := setup(t, domain.CatchAllSettings{Enabled: true, MailboxID: "mb-inbox"})
+
+		require.NoError(t, f.deliver("someone@example.net", headerMessage("hello"), "unknown@catch.example"))
+		messages, err := f.store.ListMessages(t.Context(), "mb-inbox")
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "unknown@catch.example", messages[0].To)
+		assert.Len(t, f.store.ListMailboxes(t.Context()), 1)
+	})
+
+	t.Run("自动创建邮箱", func(t *testing.T) {
+		f := setup(t, domain.CatchAllSettings{Enabled: true})
+
+		require.NoError(t, f.deliver("someone@example.net", headerMessage("hello"), "signup-42@catch.example"))
+		mailbox, err := f.store.GetMailboxByAddress(t.Context(), "signup-42@catch.example")
+		require.NoError(t, err)
+		require.NotNil(t, mailbox.UserID)
+		assert.Equal(t, owner, *mailbox.UserID)
+		messages, err := f.store.ListMessages(t.Context(), mailbox.ID)
+		require.NoError(t, err)
+		assert.Len(t, messages, 1)
+	})
+
+	t.Run("关闭时按不存在拒收", func(t *testing.T) {
+		f := setup(t, domain.CatchAllSettings{})
+
+		err := f.deliver("someone@example.net", headerMessage("hello"), "unknown@catch.example")
+		var smtpErr *gosmtp.SMTPError
+		require.True(t, errors.As(err, &smtpErr), "expected SMTP error, got %v", err)
+		assert.Equal(t, 550, smtpErr.Code)
+	})
+}
+
+func TestSession_DomainWhitelist(t *testing.T) {
+	owner := "user-1"
+	f := newIngestFixture(t)
+	require.NoError(t, f.store.SaveUserDomain(t.Context(), &domain.UserDomain{
+		ID: "ud-1", UserID: owner, Domain: "list.example", Mode: domain.DomainModeWhitelist,
+		Status: domain.DomainStatusVerified, IsActive: true,
+	}))
+	for _, localPart := range []string{"sales", "legacy"} {
+		require.NoError(t, f.store.SaveMailbox(t.Context(), &domain.Mailbox{
+			ID: "mb-" + localPart, Address: localPart + "@list.example", LocalPart: localPart, Domain: "list.example",
+			UserID: &owner, CreatedAt: time.Now(),
+		}))
+	}
+
+	cfg := &config.Config{}
+	f.backend.mailboxes = service.NewMailboxService(f.store, f.store, cfg)
+	f.backend.systemDomains = service.NewSystemDomainService(f.store, cfg)
+	whitelist := service.NewDomainWhitelistService(f.store, f.store)
+	_, err := whitelist.Add(t.Context(), "ud-1", owner, "Sales")
+	require.NoError(t, err)
+	f.backend.SetDomainWhitelist(whitelist)
+
+	require.NoError(t, f.deliver("someone@example.net", headerMessage("hello"), "sales@list.example"))
+	messages, err := f.store.ListMessages(t.Context(), "mb-sales")
+	require.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	// 不在白名单中的前缀即使已有邮箱也拒收
+	err = f.deliver("someone@example.net", headerMessage("hello"), "legacy@list.example")
+	var smtpErr *gosmtp.SMTPError
+	require.True(t, errors.As(err, &smtpErr), "expected SMTP error, got %v", err)
+	assert.Equal(t, 550, smtpErr.Code)
+	assert.Equal(t, gosmtp.EnhancedCode{5, 1, 1}, smtpErr.EnhancedCode)
+}
+
+func TestSession_SinkMode(t *testing.T) {
+	raw := buildMessage(map[string][]byte{"a.txt": []byte("payload")}, false)
+
+	t.Run("接收任意收件人且不创建邮箱和邮件", func(t *testing.T) {
+		f := newIngestFixture(t, "diag")
+		sinks := f.withSink(t, domain.SinkSettings{})
+
+		require.NoError(t, f.deliver("someone@example.net", raw, "nobody@sink.example", "other@sink.example"))
+
+		assert.Len(t, f.store.ListMailboxes(t.Context()), 1)
+		messages, err := f.store.ListMessages(t.Context(), "diag")
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+		assert.Empty(t, f.tempFiles(t))
+		assert.Empty(t, f.blobFiles(t))
+
+		stats, err := sinks.SystemDomainStats(t.Context(), "sd-sink")
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, stats.Messages, "同一域名的多个收件人只计一封")
+		assert.EqualValues(t, len(raw), stats.Bytes)
+		assert.EqualValues(t, 1, stats.UniqueSenders)
+		require.Len(t, stats.Hourly, 1)
+		assert.EqualValues(t, 1, stats.Hourly[0].Messages)
+	})
+
+	t.Run("并发突发时计数准确", func(t *testing.T) {
+		f := newIngestFixture(t)
+		sinks := f.withSink(t, domain.SinkSettings{})
+
+		const total, senders = 64, 8
+		var wg sync.WaitGroup
+		errs := make(chan error, total)
+		for i := 0; i < total; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs <- f.deliver(fmt.Sprintf("s%d@example.net", i%senders), raw, fmt.Sprintf("r%d@sink.example", i))
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		stats, err := sinks.SystemDomainStats(t.Context(), "sd-sink")
+		require.NoError(t, err)
+		assert.EqualValues(t, total, stats.Messages)
+		assert.EqualValues(t, total*len(raw), stats.Bytes)
+		assert.EqualValues(t, senders, stats.UniqueSenders)
+	})
+
+	t.Run("按抽样率投递到诊断邮箱", func(t *testing.T) {
+		f := newIngestFixture(t, "diag")
+		sinks := f.withSink(t, domain.SinkSettings{SampleRate: 4, DiagnosticsMailboxID: "diag"})
+
+		for i := 0; i < 12; i++ {
+			require.NoError(t, f.deliver("someone@example.net", raw, "x@sink.example"))
+		}
+
+		messages, err := f.store.ListMessages(t.Context(), "diag")
+		require.NoError(t, err)
+		assert.Len(t, messages, 3)
+		stats, err := sinks.SystemDomainStats(t.Context(), "sd-sink")
+		require.NoError(t, err)
+		assert.EqualValues(t, 12, stats.Messages)
+		assert.EqualValues(t, 3, stats.Sampled)
+	})
+
+	t.Run("中途关闭后恢复正常收件", func(t *testing.T) {
+		f := newIngestFixture(t)
+		sinks := f.withSink(t, domain.SinkSettings{})
+
+		sess := &session{backend: f.backend, fromAddress: "someone@example.net"}
+		require.NoError(t, sess.Rcpt("x@sink.example", nil))
+
+		_, err := sinks.ConfigureSystemDomain(t.Context(), "sd-sink", domain.SinkSettings{Enabled: false})
+		require.NoError(t, err)
+
+		// 关闭前已接收的收件人照常计入
+		require.NoError(t, sess.Data(bytes.NewReader(raw)))
+		stats, err := sinks.SystemDomainStats(t.Context(), "sd-sink")
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, stats.Messages)
+
+		// 之后的收件人按正常流程解析，不存在的邮箱返回 550
+		var smtpErr *gosmtp.SMTPError
+		require.ErrorAs(t, f.deliver("someone@example.net", raw, "x@sink.example"), &smtpErr)
+		assert.Equal(t, 550, smtpErr.Code)
+		assert.Equal(t, gosmtp.EnhancedCode{5, 1, 1}, smtpErr.EnhancedCode)
+	})
+}
+
+// batchSpyStore 统计逐封入库与批量入库的调用次数
+type batchSpyStore struct {
+	*memory.Store
+	singles atomic.Int32
+	batches atomic.Int32
+}
+
+func (s *batchSpyStore) SaveMessage(ctx context.Context, message *domain.Message) error {
+	s.singles.Add(1)
+	return s.Store.SaveMessage(ctx, message)
+}
+
+func (s *batchSpyStore) SaveMessages(ctx context.Context, messages []*domain.Message) error {
+	s.batches.Add(1)
+	return s.Store.SaveMessages(ctx, messages)
+}
+
+// recordingRecipientMetrics 记录收件人数量指标
+type recordingRecipientMetrics struct {
+	mu       sync.Mutex
+	counts   []int
+	deferred int
+}
+
+func (m *recordingRecipientMetrics) RecordRecipientsPerTransaction(count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts = append(m.counts, count)
+}
+
+func (m *recordingRecipientMetrics) RecordRecipientDeferred() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deferred++
+}
+
+// withRecipients 使用批量统计存储重建 fixture 的服务，并将 corp.example 登记为系统域名
+func (f *ingestFixture) withRecipients(t *testing.T) *batchSpyStore {
+	t.Helper()
+	require.NoError(t, f.store.SaveSystemDomain(t.Context(), &domain.SystemDomain{
+		ID: "sd-corp", Domain: "corp.example", Status: domain.SystemDomainStatusVerified,
+		IsActive: true, CreatedAt: time.Now(),
+	}))
+	spy := &batchSpyStore{Store: f.store}
+	cfg := &config.Config{}
+	f.messages = service.NewMessageService(spy)
+	f.messages.SetFilesystemStore(f.fs)
+	f.backend.messages = f.messages
+	f.backend.mailboxes = service.NewMailboxService(f.store, f.store, cfg)
+	f.backend.systemDomains = service.NewSystemDomainService(f.store, cfg)
+	return spy
+}
+
+// rawFiles 列出所有邮件目录中的 raw.eml
+func (f *ingestFixture) rawFiles(t testing.TB) []string {
+	t.Helper()
+	var files []string
+	require.NoError(t, filepath.Walk(f.dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Name() == "raw.eml" {
+			files = append(files, path)
+		}
+		return err
+	}))
+	return files
+}
+
+func TestSession_RecipientCap(t *testing.T) {
+	raw := buildMessage(map[string][]byte{"a.txt": []byte("payload")}, false)
+	ids := func(n int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = fmt.Sprintf("mb-%02d", i)
+		}
+		return out
+	}
+
+	t.Run("超出上限的收件人返回452且可在新事务中重试", func(t *testing.T) {
+		mailboxIDs := ids(30)
+		f := newIngestFixture(t, mailboxIDs...)
+		spy := f.withRecipients(t)
+		metrics := &recordingRecipientMetrics{}
+		f.backend.SetRecipientMetrics(metrics)
+
+		sess := &session{backend: f.backend}
+		require.NoError(t, sess.Mail("sender@example.com", nil))
+		var deferred []string
+		for _, id := range mailboxIDs {
+			err := sess.Rcpt(id+"@corp.example", nil)
+			if err == nil {
+				continue
+			}
+			var smtpErr *gosmtp.SMTPError
+			require.ErrorAs(t, err, &smtpErr)
+			assert.Equal(t, 452, smtpErr.Code)
+			assert.Equal(t, gosmtp.EnhancedCode{4, 5, 3}, smtpErr.EnhancedCode)
+			deferred = append(deferred, id)
+		}
+		assert.Equal(t, mailboxIDs[DefaultMaxRecipients:], deferred)
+		require.NoError(t, sess.Data(bytes.NewReader(raw)))
+
+		// 发件方对被推迟的收件人重新发起事务
+		require.NoError(t, f.deliver("sender@example.com", raw, func() []string {
+			var out []string
+			for _, id := range deferred {
+				out = append(out, id+"@corp.example")
+			}
+			return out
+		}()...))
+
+		for _, id := range mailboxIDs {
+			messages, err := f.store.ListMessages(t.Context(), id)
+			require.NoError(t, err)
+			assert.Len(t, messages, 1, id)
+		}
+		assert.EqualValues(t, 2, spy.batches.Load(), "每个事务一次批量入库")
+		assert.Zero(t, spy.singles.Load())
+		assert.Equal(t, []int{DefaultMaxRecipients, 5}, metrics.counts)
+		assert.Equal(t, 5, metrics.deferred)
+	})
+
+	t.Run("不存在的地址返回550且不占用名额", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1", "mb-2")
+		f.withRecipients(t)
+		f.backend.SetMaxRecipients(2)
+
+		sess := &session{backend: f.backend}
+		require.NoError(t, sess.Mail("sender@example.com", nil))
+		require.NoError(t, sess.Rcpt("mb-1@corp.example", nil))
+
+		var smtpErr *gosmtp.SMTPError
+		require.ErrorAs(t, sess.Rcpt("ghost@corp.example", nil), &smtpErr)
+		assert.Equal(t, 550, smtpErr.Code)
+		assert.Equal(t, gosmtp.EnhancedCode{5, 1, 1}, smtpErr.EnhancedCode)
+
+		require.NoError(t, sess.Rcpt("mb-2@corp.example", nil))
+		require.ErrorAs(t, sess.Rcpt("mb-1@corp.example", nil), &smtpErr)
+		assert.Equal(t, 452, smtpErr.Code)
+	})
+
+	t.Run("原始内容只落盘一次并由各收件人共享", func(t *testing.T) {
+		mailboxIDs := ids(DefaultMaxRecipients)
+		f := newIngestFixture(t, mailboxIDs...)
+		f.withRecipients(t)
+
+		to := make([]string, len(mailboxIDs))
+		for i, id := range mailboxIDs {
+			to[i] = id + "@corp.example"
+		}
+		require.NoError(t, f.deliver("sender@example.com", raw, to...))
+		assert.Empty(t, f.tempFiles(t))
+
+		files := f.rawFiles(t)
+		require.Len(t, files, len(mailboxIDs))
+		first, err := os.Stat(files[0])
+		require.NoError(t, err)
+		for _, file := range files[1:] {
+			info, err := os.Stat(file)
+			require.NoError(t, err)
+			assert.True(t, os.SameFile(first, info), file)
+		}
+	})
+
+	t.Run("RCPT 之后删除的邮箱不再投递", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1", "mb-2")
+		spy := f.withRecipients(t)
+
+		sess := &session{backend: f.backend}
+		require.NoError(t, sess.Mail("sender@example.com", nil))
+		require.NoError(t, sess.Rcpt("mb-1@corp.example", nil))
+		require.NoError(t, sess.Rcpt("mb-2@corp.example", nil))
+		require.NoError(t, f.store.DeleteMailbox(t.Context(), "mb-2"))
+		require.NoError(t, sess.Data(bytes.NewReader(raw)))
+
+		messages, err := f.store.ListMessages(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Len(t, messages, 1)
+		assert.EqualValues(t, 1, spy.batches.Load())
+	})
+}
+
+// headerMessage 构造带自定义头的邮件
+func headerMessage(subject string, headers ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: ci@example.com\r\nTo: mb-1@corp.example\r\nSubject: " + subject + "\r\n")
+	for _, header := range headers {
+		buf.WriteString(header + "\r\n")
+	}
+	buf.WriteString("\r\nbody\r\n")
+	return buf.Bytes()
+}
+
+func TestSessionData_CapturedHeaders(t *testing.T) {
+	f := newIngestFixture(t, "mb-1")
+	configs := service.NewConfigService(f.store)
+	configs.SetDefaultCapturedHeaders([]string{"X-Test-Run-ID", "X-Correlation-ID", "List-Unsubscribe"})
+	f.backend.SetHeaderAllowlist(configs)
+
+	// ingest 投递邮件并按主题取回入库结果
+	ingest := func(t *testing.T, subject string, headers ...string) *domain.Message {
+		t.Helper()
+		require.NoError(t, f.session("mb-1").Data(bytes.NewReader(headerMessage(subject, headers...))))
+		messages, err := f.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		for i := range messages {
+			if messages[i].Subject == subject {
+				return &messages[i]
+			}
+		}
+		t.Fatalf("message %q not found", subject)
+		return nil
+	}
+
+	t.Run("只保存名单内的头，头名规范化", func(t *testing.T) {
+		msg := ingest(t, "allowlisted", "x-test-run-id: 4711", "X-Correlation-ID: corr-1", "X-Secret: nope")
+		assert.Equal(t, map[string]string{"X-Test-Run-Id": "4711", "X-Correlation-Id": "corr-1"}, msg.CapturedHeaders)
+	})
+
+	t.Run("没有名单内的头时不保存", func(t *testing.T) {
+		msg := ingest(t, "plain")
+		assert.Nil(t, msg.CapturedHeaders)
+	})
+
+	t.Run("超长的头值被截断", func(t *testing.T) {
+		msg := ingest(t, "long", "List-Unsubscribe: <https://example.com/u?"+strings.Repeat("a", 400)+">")
+		value := msg.CapturedHeaders["List-Unsubscribe"]
+		assert.Len(t, value, domain.MaxCapturedHeaderLength)
+		assert.True(t, strings.HasPrefix(value, "<https://example.com/u?aaa"))
+	})
+
+	t.Run("从名单移除后不再保存，已保存的值保留", func(t *testing.T) {
+		earlier := ingest(t, "before", "X-Test-Run-ID: 1000")
+
+		_, err := configs.UpdateSystemConfig(t.Context(), service.UpdateSystemConfigInput{Mailbox: &domain.MailboxConfig{
+			DefaultTTL: "24h", MaxPerIP: 3, AllowedDomains: []string{"corp.example"},
+			CapturedHeaders: []string{"X-Correlation-ID"},
+		}})
+		require.NoError(t, err)
+
+		later := ingest(t, "after", "X-Test-Run-ID: 1001", "X-Correlation-ID: corr-2")
+		assert.Equal(t, map[string]string{"X-Correlation-Id": "corr-2"}, later.CapturedHeaders)
+
+		stored, err := f.messages.Get(t.Context(), "mb-1", earlier.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "1000", stored.CapturedHeaders["X-Test-Run-Id"])
+	})
+}
+
+func TestSessionData_DKIM(t *testing.T) {
+	f := newIngestFixture(t, "mb-1")
+	pub, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	f.backend.SetDKIMVerifier(dkim.NewVerifier(func(_ context.Context, name string) ([]string, error) {
+		if name != "sel._domainkey.vendor.example" {
+			return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+		}
+		return []string{"v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(pub)}, nil
+	}))
+
+	// signed 以 simple/simple 方式签名
+	signed := func(subject, body string) []byte {
+		headers := "From: billing@vendor.example\r\nSubject: " + subject + "\r\n"
+		bodyHash := sha256.Sum256([]byte(body))
+		sig := "DKIM-Signature: v=1; a=ed25519-sha256; c=simple/simple; d=vendor.example; s=sel; h=From:Subject; bh=" +
+			base64.StdEncoding.EncodeToString(bodyHash[:]) + "; b="
+		digest := sha256.Sum256([]byte(headers + sig))
+		return []byte(sig + base64.StdEncoding.EncodeToString(ed25519.Sign(key, digest[:])) + "\r\n" + headers + "\r\n" + body)
+	}
+	ingest := func(t *testing.T, raw []byte, subject string) *domain.Message {
+		t.Helper()
+		require.NoError(t, f.session("mb-1").Data(bytes.NewReader(raw)))
+		messages, err := f.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		for i := range messages {
+			if messages[i].Subject == subject {
+				return &messages[i]
+			}
+		}
+		t.Fatalf("message %q not found", subject)
+		return nil
+	}
+
+	msg := ingest(t, signed("genuine", "pay invoice 42\r\n"), "genuine")
+	assert.Equal(t, domain.DKIMResultPass, msg.DKIMResult)
+	assert.Equal(t, "vendor.example", msg.DKIMDomain)
+
+	tampered := bytes.Replace(signed("tampered", "pay invoice 42\r\n"), []byte("invoice 42"), []byte("invoice 66"), 1)
+	msg = ingest(t, tampered, "tampered")
+	assert.Equal(t, domain.DKIMResultFail, msg.DKIMResult)
+	assert.Equal(t, "vendor.example", msg.DKIMDomain)
+
+	msg = ingest(t, headerMessage("unsigned"), "unsigned")
+	assert.Equal(t, domain.DKIMResultNone, msg.DKIMResult)
+	assert.Empty(t, msg.DKIMDomain)
+}
+
+// txtResolver 只有 TXT 记录的 DNS
+type txtResolver map[string]string
+
+func (r txtResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if record, ok := r[name]; ok {
+		return []string{record}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r txtResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (r txtResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func TestSessionData_Authentication(t *testing.T) {
+	resolver := txtResolver{
+		"example.com":        "v=spf1 ip4:192.0.2.0/24 -all",
+		"_dmarc.example.com": "v=DMARC1; p=reject",
+	}
+	session := func(f *ingestFixture, ip string) *session {
+		sess := f.session("mb-1")
+		sess.remoteIP = net.ParseIP(ip)
+		sess.helo = "mx.example.com"
+		return sess
+	}
+
+	t.Run("通过时只记录结果", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		f.backend.SetAuthenticator(mailauth.NewAuthenticator(resolver, domain.AuthActionReject))
+
+		require.NoError(t, session(f, "192.0.2.10").Data(bytes.NewReader(headerMessage("authentic"))))
+		messages, err := f.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.NotNil(t, messages[0].AuthResults)
+		assert.Equal(t, domain.SPFResultPass, messages[0].AuthResults.SPF)
+		assert.Equal(t, domain.DMARCResultPass, messages[0].AuthResults.DMARC)
+		assert.Empty(t, messages[0].AuthResults.Action)
+	})
+
+	t.Run("未通过时拒收", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		f.backend.SetAuthenticator(mailauth.NewAuthenticator(resolver, domain.AuthActionReject))
+
+		err := session(f, "10.0.0.1").Data(bytes.NewReader(headerMessage("forged")))
+		var smtpErr *gosmtp.SMTPError
+		require.True(t, errors.As(err, &smtpErr), "expected SMTP error, got %v", err)
+		assert.Equal(t, 550, smtpErr.Code)
+		messages, err := f.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+	})
+
+	t.Run("未通过时隔离", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		f.backend.SetAuthenticator(mailauth.NewAuthenticator(resolver, domain.AuthActionQuarantine))
+
+		require.NoError(t, session(f, "10.0.0.1").Data(bytes.NewReader(headerMessage("forged"))))
+		inbox, err := f.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Empty(t, inbox)
+		quarantined, err := f.messages.ListQuarantined(t.Context(), "mb-1")
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1)
+		assert.Equal(t, domain.DMARCResultFail, quarantined[0].AuthResults.DMARC)
+		assert.Equal(t, domain.AuthActionQuarantine, quarantined[0].AuthResults.Action)
+	})
+
+	t.Run("未通过时照常投递并标记", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		f.backend.SetAuthenticator(mailauth.NewAuthenticator(resolver, domain.AuthActionAccept))
+
+		require.NoError(t, session(f, "10.0.0.1").Data(bytes.NewReader(headerMessage("forged"))))
+		messages, err := f.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.False(t, messages[0].Quarantined)
+		assert.True(t, messages[0].AuthResults.Failed())
+		assert.Equal(t, domain.AuthActionAccept, messages[0].AuthResults.Action)
+	})
+}
+
+// hangingStore 批量入库时阻塞到 ctx 取消，模拟挂起的数据库
+type hangingStore struct {
+	*memory.Store
+	started chan struct{}
+}
+
+func (s *hangingStore) SaveMessages(ctx context.Context, _ []*domain.Message) error {
+	close(s.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestSession_ContextCancelledOnShutdown(t *testing.T) {
+	f := newIngestFixture(t, "mb-1")
+	hanging := &hangingStore{Store: f.store, started: make(chan struct{})}
+	f.backend.messages = service.NewMessageService(hanging)
+
+	serverCtx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+	f.backend.SetBaseContext(serverCtx)
+
+	gs, err := f.backend.NewSession(nil)
+	require.NoError(t, err)
+	sess := gs.(*session)
+	sess.fromAddress = "sender@example.com"
+	sess.recipients = []recipient{{address: "mb-1@corp.example", id: "mb-1"}}
+
+	done := make(chan error, 1)
+	go func() { done <- sess.Data(bytes.NewReader(buildMessage(nil, false))) }()
+
+	select {
+	case <-hanging.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("store call not started")
+	}
+	shutdown()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled, "服务关闭时中止进行中的存储调用")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Data did not return after shutdown")
+	}
+	assert.Empty(t, f.tempFiles(t), "临时文件已清理")
+
+	t.Run("Logout 取消会话上下文", func(t *testing.T) {
+		f.backend.SetBaseContext(context.Background())
+		gs, err := f.backend.NewSession(nil)
+		require.NoError(t, err)
+		sess := gs.(*session)
+		require.NoError(t, sess.context().Err())
+		require.NoError(t, sess.Logout())
+		assert.Error(t, sess.context().Err())
+	})
+}
+
+// relaySpy 记录经中继发出的邮件（转发在 goroutine 中投递）
+type relaySpy struct {
+	mu sync.Mutex
+	to []string
+}
+
+func (r *relaySpy) Send(_ context.Context, _ string, to []string, _ []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.to = append(r.to, to...)
+	return nil
+}
+
+func (r *relaySpy) recipients() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.to...)
+}
+
+func TestSessionData_Forwarding(t *testing.T) {
+	f := newIngestFixture(t, "mb-1", "mb-2")
+	relay := &relaySpy{}
+	f.backend.SetForwardingService(service.NewForwardingService(f.store, f.store, f.messages, relay, service.ForwardOptions{}))
+	for _, forward := range []*domain.MailboxForward{
+		{ID: "fw-1", MailboxID: "mb-1", Address: "me@example.org", Status: domain.ForwardStatusVerified},
+		{ID: "fw-2", MailboxID: "mb-2", Address: "pending@example.org", Status: domain.ForwardStatusPending},
+	} {
+		require.NoError(t, f.store.SaveMailboxForward(t.Context(), forward))
+	}
+
+	require.NoError(t, f.session("mb-1", "mb-2").Data(bytes.NewReader(headerMessage("hello"))))
+
+	// 只转发到已确认的地址
+	require.Eventually(t, func() bool { return len(relay.recipients()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"me@example.org"}, relay.recipients())
+}
+
+// signatureScanner 内容包含特征串时报告威胁
+type signatureScanner struct {
+	signature []byte
+	err       error
+}
+
+func (s *signatureScanner) Name() string { return "fake" }
+
+func (s *signatureScanner) Scan(ctx context.Context, content io.Reader) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Contains(data, s.signature) {
+		return "Eicar-Test-Signature", nil
+	}
+	return "", nil
+}
+
+// recordingScanMetrics 记录扫描指标
+type recordingScanMetrics struct {
+	outcomes []string
+}
+
+func (m *recordingScanMetrics) RecordAttachmentScan(outcome string) {
+	m.outcomes = append(m.outcomes, outcome)
+}
+
+func TestSessionData_AttachmentScan(t *testing.T) {
+	signature := []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")
+	withScanner := func(f *ingestFixture, scanner service.AttachmentScanner, failOpen bool) *recordingScanMetrics {
+		metrics := &recordingScanMetrics{}
+		scan := service.NewAttachmentScanService(scanner, config.ScanConfig{Timeout: time.Second, FailOpen: failOpen})
+		scan.SetMetrics(metrics)
+		f.backend.SetAttachmentScanner(scan)
+		return metrics
+	}
+
+	t.Run("干净附件正常投递", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		metrics := withScanner(f, &signatureScanner{signature: signature}, true)
+
+		require.NoError(t, f.session("mb-1").Data(bytes.NewReader(buildMessage(map[string][]byte{"note.txt": []byte("hello")}, false))))
+		messages, err := f.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.False(t, messages[0].Attachments[0].Infected)
+		assert.Equal(t, []string{service.ScanOutcomeClean}, metrics.outcomes)
+	})
+
+	t.Run("检出病毒投递到隔离区并标记附件", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		metrics := withScanner(f, &signatureScanner{signature: signature}, true)
+
+		// 大附件流式暂存为 blob，同样需要扫描
+		large := append(randomBytes(t, int(filesystem.InlineAttachmentBytes)+1024), signature...)
+		raw := buildMessage(map[string][]byte{"invoice.zip": large}, false)
+		require.NoError(t, f.session("mb-1").Data(bytes.NewReader(raw)))
+
+		inbox, err := f.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Empty(t, inbox)
+		quarantined, err := f.messages.ListQuarantined(t.Context(), "mb-1")
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1)
+
+		attachment, err := f.messages.GetAttachment(t.Context(), "mb-1", quarantined[0].ID, quarantined[0].Attachments[0].ID)
+		require.NoError(t, err)
+		assert.True(t, attachment.Infected)
+		assert.Equal(t, "Eicar-Test-Signature", attachment.Threat)
+		assert.Equal(t, []string{service.ScanOutcomeInfected}, metrics.outcomes)
+	})
+
+	t.Run("扫描服务不可用", func(t *testing.T) {
+		raw := buildMessage(map[string][]byte{"note.txt": []byte("hello")}, false)
+
+		f := newIngestFixture(t, "mb-1")
+		metrics := withScanner(f, &signatureScanner{err: errors.New("connection refused")}, true)
+		require.NoError(t, f.session("mb-1").Data(bytes.NewReader(raw)), "fail-open 时照常投递")
+		messages, err := f.messages.List(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Len(t, messages, 1)
+		assert.Equal(t, []string{service.ScanOutcomeUnavailable}, metrics.outcomes)
+
+		f = newIngestFixture(t, "mb-1")
+		withScanner(f, &signatureScanner{err: errors.New("connection refused")}, false)
+		err = f.session("mb-1").Data(bytes.NewReader(raw))
+		var smtpErr *gosmtp.SMTPError
+		require.True(t, errors.As(err, &smtpErr), "expected SMTP error, got %v", err)
+		assert.Equal(t, 451, smtpErr.Code)
+		assert.Empty(t, f.tempFiles(t))
+	})
+}
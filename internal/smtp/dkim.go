@@ -0,0 +1,61 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"tempmail/backend/internal/dkim"
+)
+
+var errDKIMAborted = errors.New("message ingest aborted")
+
+// dkimVerification 与原始邮件落盘并行的 DKIM 验证
+//
+// 与垃圾邮件评分相同，原始邮件经管道写入另一个 goroutine；验证失败（包括读取出错）
+// 只表现为没有结果，不影响邮件本身的接收。
+type dkimVerification struct {
+	pw      *io.PipeWriter
+	stopped bool
+	done    chan struct{}
+	result  dkim.Verification
+	err     error
+}
+
+// startDKIMVerification 启动验证，原始邮件通过 Write 写入
+func startDKIMVerification(ctx context.Context, verifier *dkim.Verifier) *dkimVerification {
+	pr, pw := io.Pipe()
+	dv := &dkimVerification{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(dv.done)
+		dv.result, dv.err = verifier.Verify(ctx, pr)
+		pr.CloseWithError(dv.err)
+	}()
+	return dv
+}
+
+// Write 写入验证器，永不返回错误
+func (dv *dkimVerification) Write(p []byte) (int, error) {
+	if !dv.stopped {
+		if _, err := dv.pw.Write(p); err != nil {
+			dv.stopped = true
+		}
+	}
+	return len(p), nil
+}
+
+// finish 原始邮件写完，等待验证结果（验证出错时返回零值）
+func (dv *dkimVerification) finish() dkim.Verification {
+	dv.pw.Close()
+	<-dv.done
+	if dv.err != nil {
+		return dkim.Verification{}
+	}
+	return dv.result
+}
+
+// abort 放弃验证（收信失败时），已完成时为空操作
+func (dv *dkimVerification) abort() {
+	dv.pw.CloseWithError(errDKIMAborted)
+	<-dv.done
+}
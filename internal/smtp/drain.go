@@ -0,0 +1,66 @@
+package smtp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+)
+
+// drainPollInterval 排空期间检查进行中事务的间隔
+const drainPollInterval = 50 * time.Millisecond
+
+// errShuttingDown 排空期间拒绝新会话和新事务（421，发件方稍后重试或改投其他 MX）
+var errShuttingDown = &gosmtp.SMTPError{
+	Code:         421,
+	EnhancedCode: gosmtp.EnhancedCode{4, 3, 2},
+	Message:      "service shutting down, try again later",
+}
+
+// StartDrain 开始排空：之后的新会话和新事务返回 421，进行中的事务照常完成
+//
+// 调用方应同时关闭监听器，停止接受新连接。
+func (b *Backend) StartDrain() {
+	b.draining.Store(true)
+}
+
+// Draining 是否正在排空
+func (b *Backend) Draining() bool {
+	return b.draining.Load()
+}
+
+// WaitDrained 等待进行中的事务（已 MAIL FROM 到 DATA 结束）全部完成
+//
+// ctx 到期时仍有事务未完成则返回错误，调用方随后关闭服务器断开全部连接，
+// 未完成的投递没有得到 250 应答，发件方会稍后重试。空闲会话不影响排空。
+func (b *Backend) WaitDrained(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		active := b.sessions.inTransaction()
+		if active == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d smtp transactions still in progress: %w", active, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// inTransaction 处于事务中（MAIL、RCPT、DATA 阶段）的会话数
+func (r *SessionRegistry) inTransaction() int {
+	n := 0
+	r.sessions.Range(func(_, value any) bool {
+		t := value.(*trackedSession)
+		t.mu.Lock()
+		if t.state != SessionStateConnected {
+			n++
+		}
+		t.mu.Unlock()
+		return true
+	})
+	return n
+}
@@ -0,0 +1,73 @@
+package smtp
+
+import (
+	"context"
+	"net"
+	netsmtp "net/smtp"
+	"net/textproto"
+	"testing"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+)
+
+func TestDrain(t *testing.T) {
+	f := newIngestFixture(t)
+	f.withSink(t, domain.SinkSettings{})
+
+	server := gosmtp.NewServer(f.backend)
+	server.Domain = "localhost"
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	addr := listener.Addr().String()
+
+	// 一个会话停在 RCPT 阶段（事务进行中），一个会话空闲
+	busy, err := netsmtp.Dial(addr)
+	require.NoError(t, err)
+	require.NoError(t, busy.Hello("busy.example"))
+	require.NoError(t, busy.Mail("alice@example.net"))
+	require.NoError(t, busy.Rcpt("x@sink.example"))
+	idle, err := netsmtp.Dial(addr)
+	require.NoError(t, err)
+	require.NoError(t, idle.Hello("idle.example"))
+
+	f.backend.StartDrain()
+	require.NoError(t, listener.Close())
+
+	t.Run("事务进行中时等待到期返回错误", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), 3*drainPollInterval)
+		defer cancel()
+		err := f.backend.WaitDrained(ctx)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("不再接受新连接，空闲会话不能开始新事务", func(t *testing.T) {
+		_, err := net.DialTimeout("tcp", addr, time.Second)
+		assert.Error(t, err)
+
+		err = idle.Mail("bob@example.net")
+		var protoErr *textproto.Error
+		require.ErrorAs(t, err, &protoErr)
+		assert.Equal(t, 421, protoErr.Code)
+	})
+
+	t.Run("进行中的投递完成后排空结束", func(t *testing.T) {
+		w, err := busy.Data()
+		require.NoError(t, err)
+		_, err = w.Write([]byte("Subject: drain\r\n\r\nbody\r\n"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close(), "排空期间已开始的事务照常完成")
+		require.NoError(t, busy.Quit())
+
+		ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+		defer cancel()
+		assert.NoError(t, f.backend.WaitDrained(ctx))
+	})
+}
@@ -0,0 +1,49 @@
+package smtp
+
+import (
+	"net/mail"
+	"unicode/utf8"
+
+	"tempmail/backend/internal/domain"
+)
+
+// captureHeaders 按名单提取邮件头（解码 RFC 2047 编码，超长截断）
+//
+// 只保存名单内的头，其余头仍可从原始邮件中查看。同名头出现多次时取第一个。
+func (b *Backend) captureHeaders(header mail.Header) map[string]string {
+	if b.headers == nil || header == nil {
+		return nil
+	}
+	var captured map[string]string
+	for _, name := range b.headers.CapturedHeaders() {
+		if !domain.ValidHeaderName(name) {
+			continue
+		}
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		if captured == nil {
+			captured = make(map[string]string)
+		}
+		captured[domain.CanonicalHeaderName(name)] = truncateHeader(decodeHeader(value))
+	}
+	return captured
+}
+
+// truncateHeader 按字节上限截断头值，不截断多字节字符
+func truncateHeader(value string) string {
+	return truncateBytes(value, domain.MaxCapturedHeaderLength)
+}
+
+// truncateBytes 截断到最多 limit 字节，不截断多字节字符
+func truncateBytes(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	value = value[:limit]
+	for len(value) > 0 && !utf8.ValidString(value) {
+		value = value[:len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,26 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+)
+
+// Inject 将一封原始邮件按 SMTP 会话的流程（MAIL → RCPT → DATA）投递到本系统。
+//
+// 供开发模式的发信接口使用：收件人校验、解析、文件系统存储、通知、过滤和 Webhook
+// 与真实的 SMTP 入库完全一致。错误原样返回（*gosmtp.SMTPError 携带 SMTP 状态码）。
+// 会话上下文由 ctx 派生，ctx 取消时中止进行中的存储调用。
+func (b *Backend) Inject(ctx context.Context, from string, to []string, raw []byte) error {
+	sess := b.newSession(ctx)
+	defer sess.Logout()
+
+	if err := sess.Mail(from, nil); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := sess.Rcpt(addr, nil); err != nil {
+			return err
+		}
+	}
+	return sess.Data(bytes.NewReader(raw))
+}
@@ -0,0 +1,126 @@
+package smtp
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/devmail"
+	"tempmail/backend/internal/domain"
+)
+
+func TestBackend_Inject(t *testing.T) {
+	// storedFields 入库后可比较的字段（去掉 ID、序号、时间等每次不同的值）
+	storedFields := func(t *testing.T, f *ingestFixture, mailboxID string) []map[string]any {
+		t.Helper()
+		listed, err := f.store.ListMessages(t.Context(), mailboxID)
+		require.NoError(t, err)
+		var out []map[string]any
+		for _, item := range listed {
+			msg, err := f.messages.Get(t.Context(), mailboxID, item.ID)
+			require.NoError(t, err)
+			raw, err := f.fs.GetMessageRaw(mailboxID, msg.ID)
+			require.NoError(t, err)
+			var attachments []string
+			for _, att := range msg.Attachments {
+				attachments = append(attachments, att.Filename+"|"+att.ContentType)
+			}
+			out = append(out, map[string]any{
+				"from": msg.From, "to": msg.To, "subject": msg.Subject, "size": msg.Size,
+				"text": msg.Text, "html": msg.HTML, "hasRaw": msg.HasRaw, "raw": string(raw),
+				"attachments": attachments,
+			})
+		}
+		return out
+	}
+
+	t.Run("与 SMTP 会话入库的字段一致", func(t *testing.T) {
+		raw, err := devmail.Build(devmail.Draft{
+			From: "dev@example.com", To: "mb-1@corp.example", Subject: "Parity 测试",
+			Text: "plain body", HTML: "<p>html body</p>",
+			Attachments: []devmail.Attachment{{Filename: "a.txt", Content: []byte("payload")}},
+		})
+		require.NoError(t, err)
+
+		viaSMTP := newIngestFixture(t, "mb-1")
+		viaSMTP.withRecipients(t)
+		require.NoError(t, viaSMTP.deliver("dev@example.com", raw, "mb-1@corp.example"))
+
+		viaInject := newIngestFixture(t, "mb-1")
+		viaInject.withRecipients(t)
+		require.NoError(t, viaInject.backend.Inject(t.Context(), "dev@example.com", []string{"mb-1@corp.example"}, raw))
+
+		expected := storedFields(t, viaSMTP, "mb-1")
+		require.Len(t, expected, 1)
+		assert.Equal(t, expected, storedFields(t, viaInject, "mb-1"))
+		assert.Equal(t, "Parity 测试", expected[0]["subject"])
+		assert.Equal(t, "plain body", expected[0]["text"])
+		assert.Equal(t, "<p>html body</p>", expected[0]["html"])
+		assert.Equal(t, []string{"a.txt|text/plain"}, expected[0]["attachments"])
+	})
+
+	t.Run("非本实例的收件人返回 550", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		f.withRecipients(t)
+		raw, err := devmail.Build(devmail.Draft{To: "someone@elsewhere.example", Text: "hi"})
+		require.NoError(t, err)
+
+		for _, to := range []string{"someone@elsewhere.example", "ghost@corp.example"} {
+			var smtpErr *gosmtp.SMTPError
+			require.ErrorAs(t, f.backend.Inject(t.Context(), "dev@example.com", []string{to}, raw), &smtpErr)
+			assert.Equal(t, 550, smtpErr.Code, to)
+		}
+		listed, err := f.store.ListMessages(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Empty(t, listed)
+		assert.Empty(t, f.backend.Sessions().List(), "注入不登记活跃会话")
+	})
+
+	t.Run("内置示例邮件都能正常入库", func(t *testing.T) {
+		f := newIngestFixture(t, "mb-1")
+		f.withRecipients(t)
+		for _, name := range devmail.Templates() {
+			raw, err := devmail.Render(name, "", "mb-1@corp.example")
+			require.NoError(t, err, name)
+			require.NoError(t, f.backend.Inject(t.Context(), "dev@localhost", []string{"mb-1@corp.example"}, raw), name)
+		}
+
+		listed, err := f.store.ListMessages(t.Context(), "mb-1")
+		require.NoError(t, err)
+		require.Len(t, listed, len(devmail.Templates()))
+		bySubject := map[string]*domain.Message{}
+		for _, item := range listed {
+			msg, err := f.messages.Get(t.Context(), "mb-1", item.ID)
+			require.NoError(t, err)
+			assert.NotEmpty(t, msg.Text, msg.Subject)
+			bySubject[msg.Subject] = msg
+		}
+
+		newsletter := bySubject["This week at Example Weekly: release notes and tips"]
+		require.NotNil(t, newsletter)
+		require.Len(t, newsletter.Attachments, 1)
+		assert.Equal(t, "logo.png", newsletter.Attachments[0].Filename)
+
+		var invite *domain.Message
+		for subject, msg := range bySubject {
+			if strings.HasPrefix(subject, "Invitation:") {
+				invite = msg
+			}
+		}
+		require.NotNil(t, invite)
+		require.Len(t, invite.Attachments, 1)
+		attachment, err := f.messages.GetAttachment(t.Context(), "mb-1", invite.ID, invite.Attachments[0].ID)
+		require.NoError(t, err)
+		reader, err := attachment.Open()
+		require.NoError(t, err)
+		defer reader.Close()
+		ics, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Contains(t, string(ics), "BEGIN:VEVENT")
+		assert.Contains(t, string(ics), "ATTENDEE;ROLE=REQ-PARTICIPANT;RSVP=TRUE:mailto:mb-1@corp.example")
+	})
+}
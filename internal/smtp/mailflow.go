@@ -0,0 +1,14 @@
+package smtp
+
+// MailFlowRecorder 收信流量统计（管理后台流量看板）
+type MailFlowRecorder interface {
+	// RecordMessage 记录一封接收的邮件（recipients 为接收的收件地址）
+	RecordMessage(from string, recipients []string)
+	// RecordRejection 记录一次拒收
+	RecordRejection(reason string)
+}
+
+// SetMailFlowRecorder 设置收信流量统计，拒收原因与 RecordSMTPRejection 相同
+func (b *Backend) SetMailFlowRecorder(recorder MailFlowRecorder) {
+	b.mailFlow = recorder
+}
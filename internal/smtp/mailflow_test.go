@@ -0,0 +1,41 @@
+package smtp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/mailflow"
+)
+
+func TestSession_MailFlow(t *testing.T) {
+	f := newIngestFixture(t, "mb-1", "mb-2")
+	recorder := mailflow.NewRecorder(f.store, config.MailFlowConfig{Enabled: true}, nil)
+	f.backend.SetMailFlowRecorder(recorder)
+	f.backend.SetInboundProtection(staticPolicy{SenderDomainPerMinute: 1})
+	now := time.Now()
+	f.backend.guard.now = func() time.Time { return now }
+
+	require.NoError(t, f.session("mb-1", "mb-2").Data(bytes.NewReader(buildMessage(nil, false))))
+	require.NoError(t, (&session{backend: f.backend}).Mail("a@bulk.example", nil))
+	assertSMTPCode(t, (&session{backend: f.backend}).Mail("b@bulk.example", nil), 451)
+	require.NoError(t, recorder.Flush(t.Context()))
+
+	counters, err := f.store.ListMailFlowCounters(t.Context(), time.Time{}, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	counts := make(map[string]int64)
+	for _, counter := range counters {
+		counts[counter.Kind+":"+counter.Dimension] += counter.Count
+	}
+	assert.Equal(t, map[string]int64{
+		domain.MailFlowAccepted + ":":                          1,
+		domain.MailFlowSenderDomain + ":example.com":           1,
+		domain.MailFlowRecipientDomain + ":corp.example":       1,
+		domain.MailFlowRejected + ":" + RejectReasonSenderRate: 1,
+	}, counts)
+}
@@ -0,0 +1,54 @@
+package smtp
+
+import (
+	"errors"
+	"net"
+	netsmtp "net/smtp"
+	"net/textproto"
+	"testing"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/service"
+)
+
+func TestMaintenance_MailDeferred(t *testing.T) {
+	f := newIngestFixture(t)
+	configs := service.NewConfigService(f.store)
+	f.backend.SetMaintenanceChecker(configs)
+
+	server := gosmtp.NewServer(f.backend)
+	server.Domain = "localhost"
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	dial := func() *netsmtp.Client {
+		client, err := netsmtp.Dial(listener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = client.Close() })
+		require.NoError(t, client.Hello("sender.example"))
+		return client
+	}
+
+	_, err = configs.SetMaintenance(t.Context(), service.SetMaintenanceInput{ReadOnly: true, Message: "database upgrade"})
+	require.NoError(t, err)
+
+	t.Run("只读期间 MAIL 返回 421 和维护说明", func(t *testing.T) {
+		err := dial().Mail("alice@example.net")
+		var protoErr *textproto.Error
+		require.True(t, errors.As(err, &protoErr), "unexpected error: %v", err)
+		assert.Equal(t, 421, protoErr.Code)
+		assert.Contains(t, protoErr.Msg, "database upgrade")
+	})
+
+	_, err = configs.SetMaintenance(t.Context(), service.SetMaintenanceInput{ReadOnly: false})
+	require.NoError(t, err)
+
+	t.Run("关闭后恢复收信", func(t *testing.T) {
+		require.NoError(t, dial().Mail("alice@example.net"))
+	})
+}
@@ -2,7 +2,9 @@ package smtp
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"mime"
@@ -20,6 +22,8 @@ import (
 	"golang.org/x/text/transform"
 
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/security"
+	"tempmail/backend/internal/storage/filesystem"
 )
 
 // ParsedEmail 表示解析后的邮件内容。
@@ -30,11 +34,30 @@ type ParsedEmail struct {
 	Text        string
 	HTML        string
 	Attachments []*domain.Attachment
+	Header      mail.Header // 顶层邮件头（用于按名单保存自定义头）
+
+	staged []*filesystem.StagedBlob // 流式暂存的大附件（各收件人登记引用后释放）
+}
+
+// BlobStager 大附件流式暂存接口（由文件系统存储实现）
+type BlobStager interface {
+	StageBlob(r io.Reader) (*filesystem.StagedBlob, error)
+	OpenStagedBlob(staged *filesystem.StagedBlob) (io.ReadCloser, error)
+	ReleaseStagedBlob(staged *filesystem.StagedBlob) error
 }
 
-// ParseEmail 解析邮件，提取文本、HTML 和附件。
+// ParseEmail 解析邮件，提取文本、HTML 和附件（附件全部读入内存）。
 func ParseEmail(rawEmail []byte) (*ParsedEmail, error) {
-	msg, err := mail.ReadMessage(bytes.NewReader(rawEmail))
+	return ParseEmailStream(bytes.NewReader(rawEmail), nil)
+}
+
+// ParseEmailStream 从流中增量解析邮件。
+//
+// 正文部分逐个读入内存；附件超过 filesystem.InlineAttachmentBytes 时直接流式写入
+// stager 暂存为 blob，附件只保留 SHA256 和大小，内存峰值取决于单个正文部分而不是整封邮件。
+// stager 为 nil 时附件全部读入内存。解析失败时已暂存的附件会被释放。
+func ParseEmailStream(r io.Reader, stager BlobStager) (*ParsedEmail, error) {
+	msg, err := mail.ReadMessage(r)
 	if err != nil {
 		return nil, fmt.Errorf("parse mail: %w", err)
 	}
@@ -44,6 +67,7 @@ func ParseEmail(rawEmail []byte) (*ParsedEmail, error) {
 		From:        msg.Header.Get("From"),
 		To:          msg.Header.Get("To"),
 		Attachments: make([]*domain.Attachment, 0),
+		Header:      msg.Header,
 	}
 
 	contentType := msg.Header.Get("Content-Type")
@@ -63,7 +87,8 @@ func ParseEmail(rawEmail []byte) (*ParsedEmail, error) {
 		}
 
 		mr := multipart.NewReader(msg.Body, boundary)
-		if err := parseMultipart(mr, parsed); err != nil {
+		if err := parseMultipart(mr, parsed, stager); err != nil {
+			parsed.releaseStaged(stager)
 			return nil, fmt.Errorf("parse multipart: %w", err)
 		}
 	} else {
@@ -83,8 +108,32 @@ func ParseEmail(rawEmail []byte) (*ParsedEmail, error) {
 	return parsed, nil
 }
 
+// releaseStaged 释放流式暂存的附件引用
+func (p *ParsedEmail) releaseStaged(stager BlobStager) {
+	if stager == nil {
+		return
+	}
+	for _, staged := range p.staged {
+		stager.ReleaseStagedBlob(staged)
+	}
+	p.staged = nil
+}
+
+// stabilizeAttachmentIDs 按序号和内容哈希重新生成附件 ID（回放模式）
+func (p *ParsedEmail) stabilizeAttachmentIDs() {
+	for i, att := range p.Attachments {
+		digest := att.SHA256
+		if digest == "" {
+			sum := sha256.Sum256(att.Content)
+			digest = hex.EncodeToString(sum[:])
+		}
+		id := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", i, digest)))
+		att.ID = "att-" + hex.EncodeToString(id[:8])
+	}
+}
+
 // parseMultipart 递归解析多部分邮件。
-func parseMultipart(mr *multipart.Reader, parsed *ParsedEmail) error {
+func parseMultipart(mr *multipart.Reader, parsed *ParsedEmail, stager BlobStager) error {
 	for {
 		part, err := mr.NextPart()
 		if err == io.EOF {
@@ -117,27 +166,13 @@ func parseMultipart(mr *multipart.Reader, parsed *ParsedEmail) error {
 				// 解码文件名
 				filename = decodeHeader(filename)
 
-				// 读取附件内容
-				content, err := io.ReadAll(part)
-				if err != nil {
-					continue
-				}
-
-				// 解码附件内容（如果是 base64 编码）
-				encoding := part.Header.Get("Content-Transfer-Encoding")
-				if strings.ToLower(encoding) == "base64" {
-					decoded, err := base64.StdEncoding.DecodeString(string(content))
-					if err == nil {
-						content = decoded
-					}
-				}
-
 				attachment := &domain.Attachment{
 					ID:          uuid.NewString(),
 					Filename:    filename,
 					ContentType: mediaType,
-					Size:        int64(len(content)),
-					Content:     content,
+				}
+				if err := readAttachment(part, attachment, parsed, stager); err != nil {
+					continue
 				}
 				parsed.Attachments = append(parsed.Attachments, attachment)
 				continue
@@ -149,7 +184,7 @@ func parseMultipart(mr *multipart.Reader, parsed *ParsedEmail) error {
 			boundary := params["boundary"]
 			if boundary != "" {
 				nestedReader := multipart.NewReader(part, boundary)
-				if err := parseMultipart(nestedReader, parsed); err != nil {
+				if err := parseMultipart(nestedReader, parsed, stager); err != nil {
 					return err
 				}
 			}
@@ -176,6 +211,52 @@ func parseMultipart(mr *multipart.Reader, parsed *ParsedEmail) error {
 	return nil
 }
 
+// readAttachment 读取附件内容：小附件读入内存，大附件流式暂存为 blob
+func readAttachment(part *multipart.Part, attachment *domain.Attachment, parsed *ParsedEmail, stager BlobStager) error {
+	var content io.Reader = part
+	if strings.ToLower(strings.TrimSpace(part.Header.Get("Content-Transfer-Encoding"))) == "base64" {
+		content = base64.NewDecoder(base64.StdEncoding, part)
+	}
+
+	if stager == nil {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return err
+		}
+		attachment.Content = data
+		attachment.Size = int64(len(data))
+		classifyAttachment(attachment, data)
+		return nil
+	}
+
+	// 先读入不超过阈值的部分，判断是否需要流式暂存
+	head, err := io.ReadAll(io.LimitReader(content, filesystem.InlineAttachmentBytes+1))
+	if err != nil {
+		return err
+	}
+	classifyAttachment(attachment, head)
+	if int64(len(head)) <= filesystem.InlineAttachmentBytes {
+		attachment.Content = head
+		attachment.Size = int64(len(head))
+		return nil
+	}
+
+	staged, err := stager.StageBlob(io.MultiReader(bytes.NewReader(head), content))
+	if err != nil {
+		return err
+	}
+	parsed.staged = append(parsed.staged, staged)
+	attachment.SHA256 = staged.Hash
+	attachment.Size = staged.Size
+	attachment.SetOpener(func() (io.ReadCloser, error) { return stager.OpenStagedBlob(staged) })
+	return nil
+}
+
+// classifyAttachment 按内容开头判断附件实际类型，标记与声明类型不一致的伪装附件
+func classifyAttachment(attachment *domain.Attachment, head []byte) {
+	attachment.DetectedContentType, attachment.ContentTypeMismatch = security.ClassifyAttachment(attachment.Filename, attachment.ContentType, head)
+}
+
 // decodeBody 根据编码方式解码邮件体。
 func decodeBody(reader io.Reader, transferEncoding string, charset string) (string, error) {
 	transferEncoding = strings.ToLower(strings.TrimSpace(transferEncoding))
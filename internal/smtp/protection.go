@@ -0,0 +1,226 @@
+package smtp
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+
+	"tempmail/backend/internal/domain"
+)
+
+// 拒收原因（指标标签和收信流量统计的维度）
+const (
+	RejectReasonIPConnections    = "ip_connections"
+	RejectReasonSenderRate       = "sender_rate"
+	RejectReasonGreylisted       = "greylisted"
+	RejectReasonRelayDenied      = "relay_denied"      // 收件域名不由本系统管理或已过期
+	RejectReasonUnknownRecipient = "unknown_recipient" // 收件邮箱不存在
+	RejectReasonSpam             = "spam"              // 超过所有收件人的垃圾邮件硬阈值
+	RejectReasonAuth             = "auth"              // SPF/DMARC 认证失败且策略为拒收
+)
+
+const (
+	// pruneInterval 清理过期计数和灰名单记录的最小间隔
+	pruneInterval = time.Minute
+	// greylistPendingTTL 未通过的三元组等待重试的最长时间
+	greylistPendingTTL = 24 * time.Hour
+)
+
+var (
+	// errTooManyConnections 单 IP 并发连接数超限
+	errTooManyConnections = &gosmtp.SMTPError{
+		Code:         421,
+		EnhancedCode: gosmtp.EnhancedCode{4, 7, 0},
+		Message:      "too many connections from your IP, try again later",
+	}
+	// errSenderRateLimited 发件域名收信速率超限
+	errSenderRateLimited = &gosmtp.SMTPError{
+		Code:         451,
+		EnhancedCode: gosmtp.EnhancedCode{4, 7, 1},
+		Message:      "sender domain rate limit exceeded, try again later",
+	}
+	// errGreylisted 首次出现的三元组，发件方稍后重试即可通过
+	errGreylisted = &gosmtp.SMTPError{
+		Code:         451,
+		EnhancedCode: gosmtp.EnhancedCode{4, 7, 1},
+		Message:      "greylisted, please try again later",
+	}
+)
+
+// InboundPolicy 收信保护配置来源（由配置服务提供，可运行时修改）
+type InboundPolicy interface {
+	InboundProtection() domain.InboundProtectionConfig
+}
+
+// RejectionMetrics 收信保护拒绝指标
+type RejectionMetrics interface {
+	RecordSMTPRejection(reason string)
+}
+
+// inboundGuard 收信保护状态（单 IP 连接数、发件域名速率窗口、灰名单三元组）
+type inboundGuard struct {
+	policy InboundPolicy
+	now    func() time.Time
+
+	mu        sync.Mutex
+	conns     map[string]int           // IP -> 当前连接数
+	senders   map[string]*senderWindow // 发件域名 -> 当前分钟窗口
+	greylist  map[greylistTriple]*greylistEntry
+	lastPrune time.Time
+}
+
+// senderWindow 发件域名固定一分钟窗口内的投递计数
+type senderWindow struct {
+	start time.Time
+	count int
+}
+
+// greylistTriple 灰名单三元组（IP 按网段归并，发件方的多台出口服务器视为同一来源）
+type greylistTriple struct {
+	network string
+	from    string
+	to      string
+}
+
+type greylistEntry struct {
+	firstSeen time.Time
+	lastSeen  time.Time
+	passed    bool
+}
+
+func newInboundGuard(policy InboundPolicy) *inboundGuard {
+	return &inboundGuard{
+		policy:   policy,
+		now:      time.Now,
+		conns:    make(map[string]int),
+		senders:  make(map[string]*senderWindow),
+		greylist: make(map[greylistTriple]*greylistEntry),
+	}
+}
+
+// SetInboundProtection 设置收信保护（单 IP 连接数、发件域名速率、灰名单）
+func (b *Backend) SetInboundProtection(policy InboundPolicy) {
+	b.guard = newInboundGuard(policy)
+}
+
+// SetRejectionMetrics 设置收信保护拒绝指标
+func (b *Backend) SetRejectionMetrics(metrics RejectionMetrics) {
+	b.rejections = metrics
+}
+
+// reject 记录拒绝原因并返回对应的 SMTP 错误
+func (b *Backend) reject(reason string, err *gosmtp.SMTPError) error {
+	if b.rejections != nil {
+		b.rejections.RecordSMTPRejection(reason)
+	}
+	if b.mailFlow != nil {
+		b.mailFlow.RecordRejection(reason)
+	}
+	return err
+}
+
+// acquireConn 登记一个连接，超过单 IP 并发上限时拒绝（未登记）
+func (g *inboundGuard) acquireConn(ip net.IP) bool {
+	limit := g.policy.InboundProtection().MaxConnectionsPerIP
+	key := ip.String()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if limit > 0 && g.conns[key] >= limit {
+		return false
+	}
+	g.conns[key]++
+	return true
+}
+
+// releaseConn 连接结束时注销
+func (g *inboundGuard) releaseConn(ip net.IP) {
+	key := ip.String()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.conns[key] <= 1 {
+		delete(g.conns, key)
+		return
+	}
+	g.conns[key]--
+}
+
+// allowSender 按发件域名计数，当前分钟超过上限时拒绝（空发件人即退信不限制）
+func (g *inboundGuard) allowSender(from string) bool {
+	limit := g.policy.InboundProtection().SenderDomainPerMinute
+	at := strings.LastIndex(from, "@")
+	if limit <= 0 || at < 0 {
+		return true
+	}
+	senderDomain := strings.ToLower(from[at+1:])
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	g.prune(now)
+	window := g.senders[senderDomain]
+	if window == nil || now.Sub(window.start) >= time.Minute {
+		window = &senderWindow{start: now}
+		g.senders[senderDomain] = window
+	}
+	if window.count >= limit {
+		return false
+	}
+	window.count++
+	return true
+}
+
+// allowTriple 灰名单检查：三元组首次出现或等待时间未到时拒绝，等待后重试的三元组放行并记住
+func (g *inboundGuard) allowTriple(ip net.IP, from, to string) bool {
+	cfg := g.policy.InboundProtection().Greylisting
+	if !cfg.Enabled || ip == nil {
+		return true
+	}
+	triple := greylistTriple{network: greylistNetwork(ip), from: strings.ToLower(from), to: to}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	g.prune(now)
+	entry := g.greylist[triple]
+	if entry == nil {
+		g.greylist[triple] = &greylistEntry{firstSeen: now, lastSeen: now}
+		return false
+	}
+	entry.lastSeen = now
+	if !entry.passed && now.Sub(entry.firstSeen) < cfg.DelayDuration() {
+		return false
+	}
+	entry.passed = true
+	return true
+}
+
+// prune 定期清理过期的速率窗口和灰名单记录（调用方持有锁）
+func (g *inboundGuard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < pruneInterval {
+		return
+	}
+	g.lastPrune = now
+	for key, window := range g.senders {
+		if now.Sub(window.start) >= time.Minute {
+			delete(g.senders, key)
+		}
+	}
+	expiry := g.policy.InboundProtection().Greylisting.ExpiryDuration()
+	for key, entry := range g.greylist {
+		if !entry.passed && now.Sub(entry.firstSeen) >= greylistPendingTTL || now.Sub(entry.lastSeen) >= expiry {
+			delete(g.greylist, key)
+		}
+	}
+}
+
+// greylistNetwork IPv4 按 /24、IPv6 按 /64 归并
+func greylistNetwork(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(64, 128)).String()
+}
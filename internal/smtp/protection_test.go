@@ -0,0 +1,112 @@
+package smtp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// staticPolicy 固定的收信保护配置
+type staticPolicy domain.InboundProtectionConfig
+
+func (p staticPolicy) InboundProtection() domain.InboundProtectionConfig {
+	return domain.InboundProtectionConfig(p)
+}
+
+type recordingRejections struct {
+	mu      sync.Mutex
+	reasons []string
+}
+
+func (r *recordingRejections) RecordSMTPRejection(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reasons = append(r.reasons, reason)
+}
+
+func assertSMTPCode(t *testing.T, err error, code int) {
+	t.Helper()
+	var smtpErr *gosmtp.SMTPError
+	require.True(t, errors.As(err, &smtpErr), "expected SMTP error, got %v", err)
+	assert.Equal(t, code, smtpErr.Code)
+}
+
+func TestInboundGuard_Connections(t *testing.T) {
+	guard := newInboundGuard(staticPolicy{MaxConnectionsPerIP: 2})
+	ip := net.ParseIP("192.0.2.10")
+
+	assert.True(t, guard.acquireConn(ip))
+	assert.True(t, guard.acquireConn(ip))
+	assert.False(t, guard.acquireConn(ip), "超过单 IP 上限")
+	assert.True(t, guard.acquireConn(net.ParseIP("192.0.2.11")), "其他 IP 不受影响")
+
+	guard.releaseConn(ip)
+	assert.True(t, guard.acquireConn(ip), "连接关闭后释放名额")
+}
+
+func TestSession_SenderDomainRate(t *testing.T) {
+	f := newIngestFixture(t)
+	f.backend.SetInboundProtection(staticPolicy{SenderDomainPerMinute: 2})
+	metrics := &recordingRejections{}
+	f.backend.SetRejectionMetrics(metrics)
+	now := time.Now()
+	f.backend.guard.now = func() time.Time { return now }
+
+	for range 2 {
+		require.NoError(t, (&session{backend: f.backend}).Mail("a@bulk.example", nil))
+	}
+	assertSMTPCode(t, (&session{backend: f.backend}).Mail("b@BULK.example", nil), 451)
+	assert.NoError(t, (&session{backend: f.backend}).Mail("a@other.example", nil), "其他发件域名不受影响")
+	assert.NoError(t, (&session{backend: f.backend}).Mail("", nil), "退信（空发件人）不限制")
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, (&session{backend: f.backend}).Mail("a@bulk.example", nil), "下一分钟重新计数")
+	assert.Equal(t, []string{RejectReasonSenderRate}, metrics.reasons)
+}
+
+func TestSession_Greylisting(t *testing.T) {
+	f := newIngestFixture(t)
+	require.NoError(t, f.store.SaveUserDomain(t.Context(), &domain.UserDomain{
+		ID: "ud-1", UserID: "user-1", Domain: "grey.example", Mode: domain.DomainModeExclusive,
+		Status: domain.DomainStatusVerified, IsActive: true,
+	}))
+	require.NoError(t, f.store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "inbox@grey.example", LocalPart: "inbox", Domain: "grey.example", CreatedAt: time.Now(),
+	}))
+	cfg := &config.Config{}
+	f.backend.mailboxes = service.NewMailboxService(f.store, f.store, cfg)
+	f.backend.systemDomains = service.NewSystemDomainService(f.store, cfg)
+	f.backend.SetInboundProtection(staticPolicy{Greylisting: domain.GreylistingConfig{Enabled: true, Delay: "5m"}})
+	metrics := &recordingRejections{}
+	f.backend.SetRejectionMetrics(metrics)
+	now := time.Now()
+	f.backend.guard.now = func() time.Time { return now }
+
+	rcpt := func(ip, to string) error {
+		sess := &session{backend: f.backend, remoteIP: net.ParseIP(ip)}
+		require.NoError(t, sess.Mail("sender@example.net", nil))
+		return sess.Rcpt(to, nil)
+	}
+
+	assertSMTPCode(t, rcpt("198.51.100.7", "inbox@grey.example"), 451)
+	now = now.Add(time.Minute)
+	assertSMTPCode(t, rcpt("198.51.100.7", "inbox@grey.example"), 451)
+
+	now = now.Add(5 * time.Minute)
+	require.NoError(t, rcpt("198.51.100.8", "inbox@grey.example"), "等待后同一网段的重试放行")
+	now = now.Add(time.Hour)
+	require.NoError(t, rcpt("198.51.100.7", "inbox@grey.example"), "通过后不再等待")
+
+	assertSMTPCode(t, rcpt("198.51.100.7", "other@unmanaged.example"), 550)
+	assert.Equal(t, []string{RejectReasonGreylisted, RejectReasonGreylisted, RejectReasonRelayDenied}, metrics.reasons)
+}
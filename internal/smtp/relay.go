@@ -0,0 +1,114 @@
+package smtp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	netsmtp "net/smtp"
+	"time"
+
+	"tempmail/backend/internal/config"
+)
+
+// ErrRelayNoStartTLS 中继不支持 STARTTLS（TLS 为 starttls 时不会降级为明文）
+var ErrRelayNoStartTLS = errors.New("smtp relay does not support STARTTLS")
+
+// Relay 经外部 SMTP 中继发信（实现 service.MailSender）
+//
+// 每封邮件一个连接：EHLO → STARTTLS（或隐式 TLS）→ AUTH PLAIN（配置了用户名时）→ MAIL → RCPT → DATA。
+// 认证只在加密连接（或本机中继）上进行。
+type Relay struct {
+	addr     string
+	host     string
+	username string
+	password string
+	tlsMode  string
+	helo     string
+	timeout  time.Duration
+}
+
+// NewRelay 创建发信中继客户端，helo 为 EHLO 使用的本机域名
+func NewRelay(cfg config.OutboundConfig, helo string) *Relay {
+	host, _, err := net.SplitHostPort(cfg.RelayAddr)
+	if err != nil {
+		host = cfg.RelayAddr
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Relay{
+		addr:     cfg.RelayAddr,
+		host:     host,
+		username: cfg.Username,
+		password: cfg.Password,
+		tlsMode:  cfg.TLS,
+		helo:     helo,
+		timeout:  timeout,
+	}
+}
+
+// Send 投递一封邮件，ctx 取消或超时时中止连接
+func (r *Relay) Send(ctx context.Context, from string, to []string, message []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	tlsConfig := &tls.Config{ServerName: r.host, MinVersion: tls.VersionTLS12}
+	if r.tlsMode == "tls" {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	client, err := netsmtp.NewClient(conn, r.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Hello(r.helo); err != nil {
+		return err
+	}
+	if r.tlsMode == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return ErrRelayNoStartTLS
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if r.username != "" {
+		if err := client.Auth(netsmtp.PlainAuth("", r.username, r.password, r.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,108 @@
+package smtp
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+)
+
+// relayRecorder 记录中继收到的邮件（拒绝 reject 收件人）
+type relayRecorder struct {
+	mu     sync.Mutex
+	from   string
+	to     []string
+	data   []byte
+	reject string
+}
+
+func (r *relayRecorder) NewSession(*gosmtp.Conn) (gosmtp.Session, error) {
+	return &relaySession{r: r}, nil
+}
+
+type relaySession struct{ r *relayRecorder }
+
+func (s *relaySession) Mail(from string, _ *gosmtp.MailOptions) error {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.from = from
+	return nil
+}
+
+func (s *relaySession) Rcpt(to string, _ *gosmtp.RcptOptions) error {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	if to == s.r.reject {
+		return &gosmtp.SMTPError{Code: 550, EnhancedCode: gosmtp.EnhancedCode{5, 7, 1}, Message: "relay denied"}
+	}
+	s.r.to = append(s.r.to, to)
+	return nil
+}
+
+func (s *relaySession) Data(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.data = data
+	return err
+}
+
+func (s *relaySession) Reset()        {}
+func (s *relaySession) Logout() error { return nil }
+
+func startTestRelay(t *testing.T) (*relayRecorder, string) {
+	t.Helper()
+	recorder := &relayRecorder{reject: "blocked@example.com"}
+	server := gosmtp.NewServer(recorder)
+	server.Domain = "relay.test"
+	server.AllowInsecureAuth = true
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return recorder, listener.Addr().String()
+}
+
+func TestRelay_Send(t *testing.T) {
+	message := []byte("Subject: hi\r\n\r\nhello\r\n")
+
+	t.Run("明文中继投递", func(t *testing.T) {
+		recorder, addr := startTestRelay(t)
+		relay := NewRelay(config.OutboundConfig{RelayAddr: addr, TLS: "none", Timeout: 5 * time.Second}, "temp.example")
+
+		err := relay.Send(t.Context(), "team@temp.example", []string{"a@example.com", "b@example.com"}, message)
+		require.NoError(t, err)
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		assert.Equal(t, "team@temp.example", recorder.from)
+		assert.Equal(t, []string{"a@example.com", "b@example.com"}, recorder.to)
+		assert.Equal(t, message, recorder.data)
+	})
+
+	t.Run("中继拒绝收件人", func(t *testing.T) {
+		_, addr := startTestRelay(t)
+		relay := NewRelay(config.OutboundConfig{RelayAddr: addr, TLS: "none"}, "temp.example")
+
+		err := relay.Send(t.Context(), "team@temp.example", []string{"blocked@example.com"}, message)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "relay denied")
+	})
+
+	t.Run("要求 STARTTLS 时不降级为明文", func(t *testing.T) {
+		recorder, addr := startTestRelay(t)
+		relay := NewRelay(config.OutboundConfig{RelayAddr: addr, TLS: "starttls"}, "temp.example")
+
+		err := relay.Send(t.Context(), "team@temp.example", []string{"a@example.com"}, message)
+		assert.ErrorIs(t, err, ErrRelayNoStartTLS)
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		assert.Empty(t, recorder.data)
+	})
+}
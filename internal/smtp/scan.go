@@ -0,0 +1,34 @@
+package smtp
+
+import (
+	"errors"
+
+	gosmtp "github.com/emersion/go-smtp"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// errScanUnavailable 附件扫描服务不可用且配置为 fail-closed 时返回，发件方稍后重试
+var errScanUnavailable = &gosmtp.SMTPError{
+	Code:         451,
+	EnhancedCode: gosmtp.EnhancedCode{4, 7, 1},
+	Message:      "attachment scan temporarily unavailable, try again later",
+}
+
+// SetAttachmentScanner 设置附件病毒扫描（检出威胁的邮件投递到隔离区）
+func (b *Backend) SetAttachmentScanner(scanner *service.AttachmentScanService) {
+	b.scanner = scanner
+}
+
+// scanAttachments 扫描解析出的附件，返回是否检出威胁（检出的附件已标记）
+func (s *session) scanAttachments(attachments []*domain.Attachment) (bool, error) {
+	if s.backend.scanner == nil {
+		return false, nil
+	}
+	infected, err := s.backend.scanner.Scan(s.context(), attachments)
+	if errors.Is(err, service.ErrScannerUnavailable) {
+		return false, errScanUnavailable
+	}
+	return infected, err
+}
@@ -0,0 +1,253 @@
+package smtp
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionNotFound 会话不存在（已结束或 ID 错误）
+var ErrSessionNotFound = errors.New("smtp session not found")
+
+// SessionState SMTP 会话当前所处的阶段
+type SessionState string
+
+const (
+	SessionStateConnected SessionState = "connected" // 已 HELO，未开始事务
+	SessionStateMail      SessionState = "mail"      // 已收到 MAIL FROM
+	SessionStateRcpt      SessionState = "rcpt"      // 正在接收收件人
+	SessionStateData      SessionState = "data"      // 正在接收邮件内容
+)
+
+// maxIntrospectionValue 会话列表中 HELO 等发件方提供的值的最大长度
+const maxIntrospectionValue = 64
+
+// SessionMetrics SMTP 会话指标
+type SessionMetrics interface {
+	RecordSMTPSessionOpened()
+	RecordSMTPSessionState(from, to string) // from 为空表示新会话，to 为空表示会话结束
+	RecordSMTPSessionClosed(duration time.Duration)
+}
+
+// SessionInfo 活跃会话快照（发件地址已遮盖，HELO 已截断）
+type SessionInfo struct {
+	ID           string       `json:"id"`
+	RemoteIP     string       `json:"remoteIp"`
+	Helo         string       `json:"helo"`
+	State        SessionState `json:"state"`
+	MailFrom     string       `json:"mailFrom,omitempty"`
+	Recipients   int          `json:"recipients"`
+	BytesRead    int64        `json:"bytesRead"`
+	StartedAt    time.Time    `json:"startedAt"`
+	LastActivity time.Time    `json:"lastActivity"`
+}
+
+// SessionRegistry 活跃 SMTP 会话登记表（排查卡住的投递）
+//
+// 每个 SMTP 回调都会更新会话，登记表用 sync.Map 保存、每个会话单独加锁，
+// 回调之间互不竞争；读取字节数用原子计数，DATA 期间不加锁。
+type SessionRegistry struct {
+	sessions sync.Map // id -> *trackedSession
+	metrics  SessionMetrics
+	now      func() time.Time
+}
+
+// NewSessionRegistry 创建会话登记表
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{now: time.Now}
+}
+
+// SetMetrics 设置会话指标
+func (r *SessionRegistry) SetMetrics(metrics SessionMetrics) {
+	r.metrics = metrics
+}
+
+// trackedSession 登记表中的单个会话
+type trackedSession struct {
+	registry  *SessionRegistry
+	id        string
+	remoteIP  string
+	helo      string
+	startedAt time.Time
+	closer    func() error // 强制关闭时断开连接
+	bytesRead atomic.Int64
+
+	mu           sync.Mutex
+	state        SessionState
+	mailFrom     string
+	recipients   int
+	lastActivity time.Time
+}
+
+// open 登记新会话；closer 为空时强制关闭只移除登记
+func (r *SessionRegistry) open(remoteAddr net.Addr, helo string, closer func() error) *trackedSession {
+	now := r.now()
+	t := &trackedSession{
+		registry:     r,
+		id:           uuid.New().String(),
+		remoteIP:     remoteIP(remoteAddr),
+		helo:         truncateValue(helo),
+		startedAt:    now,
+		closer:       closer,
+		state:        SessionStateConnected,
+		lastActivity: now,
+	}
+	r.sessions.Store(t.id, t)
+	if r.metrics != nil {
+		r.metrics.RecordSMTPSessionOpened()
+		r.metrics.RecordSMTPSessionState("", string(SessionStateConnected))
+	}
+	return t
+}
+
+// List 返回所有活跃会话（按开始时间排序）
+func (r *SessionRegistry) List() []SessionInfo {
+	sessions := make([]SessionInfo, 0)
+	r.sessions.Range(func(_, value any) bool {
+		sessions = append(sessions, value.(*trackedSession).info())
+		return true
+	})
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].StartedAt.Before(sessions[j].StartedAt) })
+	return sessions
+}
+
+// Len 活跃会话数
+func (r *SessionRegistry) Len() int {
+	n := 0
+	r.sessions.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// Close 强制关闭会话：断开连接并移除登记，发件方会看到连接中断
+func (r *SessionRegistry) Close(id string) error {
+	value, ok := r.sessions.Load(id)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	t := value.(*trackedSession)
+	var err error
+	if t.closer != nil {
+		err = t.closer()
+	}
+	t.close()
+	return err
+}
+
+// setState 更新会话阶段和最近活动时间（nil 安全，测试中直接构造的会话未登记）
+func (t *trackedSession) setState(state SessionState) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	previous := t.state
+	t.state = state
+	t.lastActivity = t.registry.now()
+	if state == SessionStateConnected {
+		t.mailFrom, t.recipients = "", 0
+	}
+	t.mu.Unlock()
+
+	if previous != state && t.registry.metrics != nil {
+		t.registry.metrics.RecordSMTPSessionState(string(previous), string(state))
+	}
+}
+
+// setMailFrom 记录发件地址
+func (t *trackedSession) setMailFrom(from string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.mailFrom = from
+	t.mu.Unlock()
+}
+
+// setRecipients 记录已接受的收件人数
+func (t *trackedSession) setRecipients(n int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.recipients = n
+	t.lastActivity = t.registry.now()
+	t.mu.Unlock()
+}
+
+// addBytes 累计读取的邮件字节数
+func (t *trackedSession) addBytes(n int) {
+	if t == nil || n <= 0 {
+		return
+	}
+	t.bytesRead.Add(int64(n))
+}
+
+// close 移除登记（可重复调用，只有第一次记录指标）
+func (t *trackedSession) close() {
+	if t == nil {
+		return
+	}
+	if _, loaded := t.registry.sessions.LoadAndDelete(t.id); !loaded {
+		return
+	}
+	if metrics := t.registry.metrics; metrics != nil {
+		t.mu.Lock()
+		state := t.state
+		t.mu.Unlock()
+		metrics.RecordSMTPSessionState(string(state), "")
+		metrics.RecordSMTPSessionClosed(t.registry.now().Sub(t.startedAt))
+	}
+}
+
+func (t *trackedSession) info() SessionInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return SessionInfo{
+		ID:           t.id,
+		RemoteIP:     t.remoteIP,
+		Helo:         t.helo,
+		State:        t.state,
+		MailFrom:     maskAddress(t.mailFrom),
+		Recipients:   t.recipients,
+		BytesRead:    t.bytesRead.Load(),
+		StartedAt:    t.startedAt,
+		LastActivity: t.lastActivity,
+	}
+}
+
+// remoteIP 取连接的对端 IP（无端口）
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// truncateValue 截断发件方提供的值
+func truncateValue(value string) string {
+	return truncateBytes(strings.TrimSpace(value), maxIntrospectionValue)
+}
+
+// maskAddress 遮盖发件地址的本地部分，只保留首字符和域名
+func maskAddress(addr string) string {
+	addr = truncateValue(addr)
+	local, domainPart, ok := strings.Cut(addr, "@")
+	if !ok || local == "" {
+		return addr
+	}
+	_, size := utf8.DecodeRuneInString(local)
+	return local[:size] + "***@" + domainPart
+}
@@ -0,0 +1,228 @@
+package smtp
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	netsmtp "net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+)
+
+// sessionMetricsSpy 记录会话指标（按阶段的当前会话数）
+type sessionMetricsSpy struct {
+	mu     sync.Mutex
+	active int
+	states map[string]int
+	closed int
+}
+
+func (m *sessionMetricsSpy) RecordSMTPSessionOpened() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active++
+}
+
+func (m *sessionMetricsSpy) RecordSMTPSessionState(from, to string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if from != "" {
+		m.states[from]--
+	}
+	if to != "" {
+		m.states[to]++
+	}
+}
+
+func (m *sessionMetricsSpy) RecordSMTPSessionClosed(time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active--
+	m.closed++
+}
+
+func (m *sessionMetricsSpy) snapshot() (active int, states map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	states = make(map[string]int)
+	for state, n := range m.states {
+		if n != 0 {
+			states[state] = n
+		}
+	}
+	return m.active, states
+}
+
+// sessionByHelo 按 HELO 查找活跃会话
+func sessionByHelo(registry *SessionRegistry, helo string) (SessionInfo, bool) {
+	for _, info := range registry.List() {
+		if info.Helo == helo {
+			return info, true
+		}
+	}
+	return SessionInfo{}, false
+}
+
+func TestSessionRegistry(t *testing.T) {
+	f := newIngestFixture(t)
+	f.withSink(t, domain.SinkSettings{})
+	metrics := &sessionMetricsSpy{states: make(map[string]int)}
+	registry := f.backend.Sessions()
+	registry.SetMetrics(metrics)
+
+	server := gosmtp.NewServer(f.backend)
+	server.Domain = "localhost"
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	addr := listener.Addr().String()
+
+	dial := func(helo string) *netsmtp.Client {
+		t.Helper()
+		client, err := netsmtp.Dial(addr)
+		require.NoError(t, err)
+		require.NoError(t, client.Hello(helo))
+		return client
+	}
+
+	// 四个并发会话分别停在不同阶段
+	var wg sync.WaitGroup
+	clients := make(map[string]*netsmtp.Client)
+	var mu sync.Mutex
+	for _, helo := range []string{"idle.example", "mail.example", "rcpt.example"} {
+		wg.Add(1)
+		go func(helo string) {
+			defer wg.Done()
+			client := dial(helo)
+			mu.Lock()
+			clients[helo] = client
+			mu.Unlock()
+		}(helo)
+	}
+	wg.Wait()
+
+	require.NoError(t, clients["mail.example"].Mail("alice@example.net"))
+	require.NoError(t, clients["rcpt.example"].Mail("bob@example.net"))
+	require.NoError(t, clients["rcpt.example"].Rcpt("x@sink.example"))
+
+	// DATA 阶段用原始连接，只发送部分内容（net/smtp 会缓冲到结束才发送）
+	dataConn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = dataConn.Close() })
+	sender := textproto.NewConn(dataConn)
+	for _, step := range []struct {
+		cmd  string
+		code int
+	}{
+		{"", 220},
+		{"HELO data.example", 250},
+		{"MAIL FROM:<carol@example.net>", 250},
+		{"RCPT TO:<x@sink.example>", 250},
+		{"DATA", 354},
+	} {
+		if step.cmd != "" {
+			require.NoError(t, sender.PrintfLine("%s", step.cmd))
+		}
+		_, _, err := sender.ReadResponse(step.code)
+		require.NoError(t, err, step.cmd)
+	}
+	// 内容读取器填满缓冲区才返回，发送足够多的数据让服务端读到一部分
+	partial := "Subject: stuck\r\n\r\n" + strings.Repeat("partial body line\r\n", 8192)
+	_, err = dataConn.Write([]byte(partial))
+	require.NoError(t, err)
+
+	t.Run("列出活跃会话及其阶段", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			info, ok := sessionByHelo(registry, "data.example")
+			return ok && info.State == SessionStateData && info.BytesRead > 0
+		}, 2*time.Second, 10*time.Millisecond)
+
+		require.Len(t, registry.List(), 4)
+		expected := map[string]SessionState{
+			"idle.example": SessionStateConnected,
+			"mail.example": SessionStateMail,
+			"rcpt.example": SessionStateRcpt,
+			"data.example": SessionStateData,
+		}
+		for helo, state := range expected {
+			info, ok := sessionByHelo(registry, helo)
+			require.True(t, ok, helo)
+			assert.Equal(t, state, info.State, helo)
+			assert.Equal(t, "127.0.0.1", info.RemoteIP)
+			assert.NotEmpty(t, info.ID)
+			assert.False(t, info.LastActivity.Before(info.StartedAt))
+		}
+
+		rcpt, _ := sessionByHelo(registry, "rcpt.example")
+		assert.Equal(t, 1, rcpt.Recipients)
+		assert.Equal(t, "b***@example.net", rcpt.MailFrom, "发件地址遮盖")
+
+		active, states := metrics.snapshot()
+		assert.Equal(t, 4, active)
+		assert.Equal(t, map[string]int{"connected": 1, "mail": 1, "rcpt": 1, "data": 1}, states)
+	})
+
+	t.Run("RSET 后回到 connected 并清空收件人", func(t *testing.T) {
+		require.NoError(t, clients["rcpt.example"].Reset())
+		info, ok := sessionByHelo(registry, "rcpt.example")
+		require.True(t, ok)
+		assert.Equal(t, SessionStateConnected, info.State)
+		assert.Zero(t, info.Recipients)
+		assert.Empty(t, info.MailFrom)
+	})
+
+	t.Run("强制关闭卡住的会话，发件方看到连接中断", func(t *testing.T) {
+		info, ok := sessionByHelo(registry, "data.example")
+		require.True(t, ok)
+		require.NoError(t, registry.Close(info.ID))
+
+		_, ok = sessionByHelo(registry, "data.example")
+		assert.False(t, ok)
+		require.NoError(t, dataConn.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, err := sender.ReadLine()
+		assert.Error(t, err, "连接已被服务端断开")
+		var netErr net.Error
+		assert.False(t, errors.As(err, &netErr) && netErr.Timeout(), "应立即断开而不是超时")
+		assert.ErrorIs(t, registry.Close(info.ID), ErrSessionNotFound)
+	})
+
+	t.Run("客户端突然断开后不残留登记", func(t *testing.T) {
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		reader := bufio.NewReader(conn)
+		_, err = reader.ReadString('\n') // 220 问候
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("HELO abrupt.example\r\nMAIL FROM:<dave@example.net>\r\n"))
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			info, ok := sessionByHelo(registry, "abrupt.example")
+			return ok && info.State == SessionStateMail
+		}, 2*time.Second, 10*time.Millisecond)
+
+		require.NoError(t, conn.Close())
+		require.Eventually(t, func() bool {
+			_, ok := sessionByHelo(registry, "abrupt.example")
+			return !ok
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("全部断开后登记表和指标归零", func(t *testing.T) {
+		for _, client := range clients {
+			assert.NoError(t, client.Quit())
+		}
+		require.Eventually(t, func() bool { return registry.Len() == 0 }, 2*time.Second, 10*time.Millisecond)
+		active, states := metrics.snapshot()
+		assert.Zero(t, active)
+		assert.Empty(t, states)
+	})
+}
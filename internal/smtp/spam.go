@@ -0,0 +1,113 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	gosmtp "github.com/emersion/go-smtp"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/spam"
+)
+
+var (
+	errSpamScoringDone    = errors.New("spam scoring finished")
+	errSpamScoringAborted = errors.New("message ingest aborted")
+)
+
+// errSpamUnavailable 评分服务不可用且配置为 fail-closed 时返回，发件方稍后重试
+var errSpamUnavailable = &gosmtp.SMTPError{
+	Code:         451,
+	EnhancedCode: gosmtp.EnhancedCode{4, 7, 1},
+	Message:      "spam check temporarily unavailable, try again later",
+}
+
+// errSpamRejected 分数超过所有收件人的硬阈值
+var errSpamRejected = &gosmtp.SMTPError{
+	Code:         550,
+	EnhancedCode: gosmtp.EnhancedCode{5, 7, 1},
+	Message:      "message rejected as spam",
+}
+
+// spamScoring 与原始邮件落盘并行的评分
+//
+// 原始邮件边读边写入管道，评分服务在另一个 goroutine 中读取；评分服务提前返回、
+// 出错或超时后写入自动停止，不影响邮件本身的接收。
+type spamScoring struct {
+	pw      *io.PipeWriter
+	stopped bool
+	done    chan struct{}
+	result  *spam.Result
+	err     error
+}
+
+// startSpamScoring 启动评分，原始邮件通过 Write 写入
+func startSpamScoring(filter *spam.Filter, env spam.Envelope) *spamScoring {
+	pr, pw := io.Pipe()
+	sc := &spamScoring{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(sc.done)
+		sc.result, sc.err = filter.Check(pr, env)
+		pr.CloseWithError(errSpamScoringDone)
+	}()
+	return sc
+}
+
+// Write 写入评分服务，永不返回错误（评分失败不能中断收信）
+func (sc *spamScoring) Write(p []byte) (int, error) {
+	if !sc.stopped {
+		if _, err := sc.pw.Write(p); err != nil {
+			sc.stopped = true
+		}
+	}
+	return len(p), nil
+}
+
+// finish 原始邮件写完，等待评分结果
+func (sc *spamScoring) finish() (*spam.Result, error) {
+	sc.pw.Close()
+	<-sc.done
+	return sc.result, sc.err
+}
+
+// abort 放弃评分（收信失败时），已完成时为空操作
+func (sc *spamScoring) abort() {
+	sc.pw.CloseWithError(errSpamScoringAborted)
+	<-sc.done
+}
+
+// UserLookup 邮箱所有者查询（用户级垃圾邮件阈值）
+type UserLookup interface {
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+}
+
+// SetUserLookup 设置邮箱所有者查询，未设置时只使用系统和邮箱阈值
+func (b *Backend) SetUserLookup(users UserLookup) {
+	b.users = users
+}
+
+// spamVerdict 按收件邮箱的有效阈值判断处理方式（分发列表使用系统阈值）
+//
+// mailbox 为批量查询到的直投邮箱；别名收件人为 nil，此时按邮箱ID单独查询。
+// owners 缓存本次事务已查询的所有者，多个收件邮箱属于同一用户时只查一次。
+func (s *session) spamVerdict(rcpt recipient, mailbox *domain.Mailbox, result *spam.Result, owners map[string]*domain.User) spam.Verdict {
+	if result == nil {
+		return spam.VerdictDeliver
+	}
+	filter := s.backend.spamFilter
+	if mailbox == nil && rcpt.list == nil && rcpt.sink == nil && s.backend.mailboxes != nil {
+		mailbox, _ = s.backend.mailboxes.Get(s.context(), rcpt.id)
+	}
+	var owner *domain.User
+	if mailbox != nil && mailbox.UserID != nil && s.backend.users != nil {
+		var ok bool
+		if owner, ok = owners[*mailbox.UserID]; !ok {
+			owner, _ = s.backend.users.GetUserByID(s.context(), *mailbox.UserID)
+			owners[*mailbox.UserID] = owner
+		}
+	}
+	verdict := filter.Thresholds(owner, mailbox).Verdict(result.Score)
+	filter.Record(verdict)
+	return verdict
+}
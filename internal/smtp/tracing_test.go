@@ -0,0 +1,77 @@
+package smtp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/instrumented"
+)
+
+func TestSession_Spans(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	// 存储调用经 instrumented 包装，应挂在 smtp.data 下
+	f := newIngestFixture(t, "mb-1")
+	store := instrumented.NewStore(f.store, "memory", instrumented.NewRecorder(prometheus.NewRegistry(), time.Hour, 0))
+	messages := service.NewMessageService(store)
+	messages.SetFilesystemStore(f.fs)
+	backend := NewBackend(nil, messages, nil, nil, nil, nil, f.fs)
+
+	sess := backend.newSession(t.Context())
+	sess.helo = "sender.example"
+	sess.startSpan("192.0.2.1:2525")
+	sess.fromAddress = "sender@example.com"
+	sess.recipients = []recipient{{address: "mb-1@corp.example", id: "mb-1"}}
+
+	raw := "From: a@example.com\r\nTo: mb-1@corp.example\r\nSubject: hi\r\n\r\nhello\r\n"
+	require.NoError(t, sess.Data(strings.NewReader(raw)))
+	backend.SetMaxMessageBytes(16)
+	require.Error(t, sess.Data(strings.NewReader(raw)), "超出大小限制")
+	require.NoError(t, sess.Logout())
+
+	var session sdktrace.ReadOnlySpan
+	var data, stores []sdktrace.ReadOnlySpan
+	for _, span := range spans.Ended() {
+		switch {
+		case span.Name() == "smtp.session":
+			session = span
+		case span.Name() == "smtp.data":
+			data = append(data, span)
+		case strings.HasPrefix(span.Name(), "store."):
+			stores = append(stores, span)
+		}
+	}
+
+	require.NotNil(t, session)
+	assert.Equal(t, trace.SpanKindServer, session.SpanKind())
+	assert.Contains(t, session.Attributes(), attribute.String("client.address", "192.0.2.1:2525"))
+	assert.Contains(t, session.Attributes(), attribute.String("smtp.helo", "sender.example"))
+
+	require.Len(t, data, 2)
+	for _, span := range data {
+		assert.Equal(t, session.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Contains(t, span.Attributes(), attribute.Int("smtp.recipients", 1))
+	}
+	assert.Equal(t, codes.Unset, data[0].Status().Code)
+	assert.Equal(t, codes.Error, data[1].Status().Code)
+
+	require.NotEmpty(t, stores)
+	for _, span := range stores {
+		assert.Equal(t, data[0].SpanContext().SpanID(), span.Parent().SpanID(), span.Name())
+	}
+}
@@ -0,0 +1,59 @@
+package spam
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Fake 测试用评分服务：读完原始邮件后返回固定结果
+type Fake struct {
+	Result *Result
+	Err    error
+	Delay  time.Duration // 返回前等待的时间（用于模拟超时，ctx 取消时提前返回）
+
+	mu    sync.Mutex
+	raw   []byte
+	calls int
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) Check(ctx context.Context, raw io.Reader, _ Envelope) (*Result, error) {
+	data, err := io.ReadAll(raw)
+	f.mu.Lock()
+	f.raw = data
+	f.calls++
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	result := *f.Result
+	return &result, nil
+}
+
+// Raw 最近一次收到的原始邮件
+func (f *Fake) Raw() []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.raw
+}
+
+// Calls 调用次数
+func (f *Fake) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
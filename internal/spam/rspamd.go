@@ -0,0 +1,73 @@
+package spam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// maxErrorBody 读取错误响应体的最大字节数
+const maxErrorBody = 1024
+
+// rspamd Rspamd HTTP 接口（/checkv2）
+type rspamd struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewRspamd 创建 Rspamd 评分服务（超时由调用方的 ctx 控制）
+func NewRspamd(address string) Scorer {
+	address = strings.TrimRight(address, "/")
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	return &rspamd{endpoint: address + "/checkv2", client: &http.Client{}}
+}
+
+func (r *rspamd) Name() string { return ProviderRspamd }
+
+// Check 流式提交原始邮件（分块传输），不需要预先知道邮件大小
+func (r *rspamd) Check(ctx context.Context, raw io.Reader, env Envelope) (*Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, raw)
+	if err != nil {
+		return nil, err
+	}
+	if env.From != "" {
+		req.Header.Set("From", env.From)
+	}
+	for _, rcpt := range env.Recipients {
+		req.Header.Add("Rcpt", rcpt)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, fmt.Errorf("rspamd: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var payload struct {
+		Score   float64                    `json:"score"`
+		Action  string                     `json:"action"`
+		Symbols map[string]json.RawMessage `json:"symbols"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("rspamd: invalid response: %w", err)
+	}
+
+	symbols := make([]string, 0, len(payload.Symbols))
+	for name := range payload.Symbols {
+		symbols = append(symbols, name)
+	}
+	sort.Strings(symbols)
+
+	return &Result{Score: payload.Score, Action: payload.Action, Symbols: symbols}, nil
+}
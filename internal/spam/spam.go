@@ -0,0 +1,202 @@
+package spam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+)
+
+// 支持的评分服务提供方
+const (
+	ProviderOff    = "off"
+	ProviderRspamd = "rspamd"
+)
+
+var (
+	ErrNotConfigured   = errors.New("spam provider not configured")
+	ErrUnknownProvider = errors.New("unknown spam provider")
+	ErrMissingAddress  = errors.New("spam provider address required")
+	ErrUnavailable     = errors.New("spam provider unavailable")
+)
+
+// 默认阈值（配置缺失时使用）
+const (
+	DefaultQuarantineScore = 6.0
+	DefaultRejectScore     = 15.0
+	DefaultMaxMailboxScore = 50.0
+)
+
+// Envelope 邮件信封信息（随原始邮件一起提交给评分服务）
+type Envelope struct {
+	From       string
+	Recipients []string
+}
+
+// Result 评分结果
+type Result struct {
+	Score   float64  // 总分
+	Action  string   // 评分服务建议的动作（如 Rspamd 的 "add header"）
+	Symbols []string // 命中的规则名（按名称排序）
+}
+
+// Scorer 评分服务接口
+//
+// raw 为原始邮件流，可能在投递过程中边写边读；实现需在 ctx 取消时尽快返回。
+type Scorer interface {
+	Name() string
+	Check(ctx context.Context, raw io.Reader, env Envelope) (*Result, error)
+}
+
+// Verdict 评分后的处理方式
+type Verdict string
+
+const (
+	VerdictDeliver    Verdict = "deliver"
+	VerdictQuarantine Verdict = "quarantine"
+	VerdictReject     Verdict = "reject"
+)
+
+// Thresholds 处理阈值（<=0 表示不启用该级别）
+type Thresholds struct {
+	Quarantine float64
+	Reject     float64
+}
+
+// Verdict 根据分数判断处理方式
+func (t Thresholds) Verdict(score float64) Verdict {
+	if t.Reject > 0 && score >= t.Reject {
+		return VerdictReject
+	}
+	if t.Quarantine > 0 && score >= t.Quarantine {
+		return VerdictQuarantine
+	}
+	return VerdictDeliver
+}
+
+// Metrics 评分指标记录器（由 monitoring.Metrics 实现）
+type Metrics interface {
+	RecordSpamCheck(outcome string)
+}
+
+// OutcomeUnavailable 评分服务不可用（超时或出错）时记录的指标标签
+const OutcomeUnavailable = "unavailable"
+
+// Filter 垃圾邮件过滤器：调用评分服务并按阈值给出处理方式
+type Filter struct {
+	scorer     Scorer
+	timeout    time.Duration
+	failOpen   bool
+	thresholds Thresholds
+	maxScore   float64
+	metrics    Metrics // 可选
+}
+
+// New 根据配置创建过滤器，未启用时返回 ErrNotConfigured
+func New(cfg config.SpamConfig) (*Filter, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case "", ProviderOff, "none":
+		return nil, ErrNotConfigured
+	case ProviderRspamd:
+		if cfg.Address == "" {
+			return nil, ErrMissingAddress
+		}
+		return NewFilter(NewRspamd(cfg.Address), cfg), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, cfg.Provider)
+	}
+}
+
+// NewFilter 使用指定评分服务创建过滤器
+func NewFilter(scorer Scorer, cfg config.SpamConfig) *Filter {
+	f := &Filter{
+		scorer:   scorer,
+		timeout:  cfg.Timeout,
+		failOpen: cfg.FailOpen,
+		thresholds: Thresholds{
+			Quarantine: orDefault(cfg.QuarantineScore, DefaultQuarantineScore),
+			Reject:     orDefault(cfg.RejectScore, DefaultRejectScore),
+		},
+		maxScore: orDefault(cfg.MaxMailboxScore, DefaultMaxMailboxScore),
+	}
+	if f.timeout <= 0 {
+		f.timeout = 5 * time.Second
+	}
+	return f
+}
+
+// SetMetrics 设置评分指标记录器
+func (f *Filter) SetMetrics(metrics Metrics) {
+	f.metrics = metrics
+}
+
+// Name 评分服务名称
+func (f *Filter) Name() string {
+	return f.scorer.Name()
+}
+
+// Check 对原始邮件评分
+//
+// 评分服务超时或出错时按 fail-open 配置处理：fail-open 返回 nil 结果（照常投递、不附分数），
+// 否则返回 ErrUnavailable（SMTP 层返回 451 让发件方稍后重试）。
+func (f *Filter) Check(raw io.Reader, env Envelope) (*Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+
+	result, err := f.scorer.Check(ctx, raw, env)
+	if err != nil {
+		f.record(OutcomeUnavailable)
+		if f.failOpen {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return result, nil
+}
+
+// Thresholds 返回收件邮箱的有效阈值
+//
+// 优先级：邮箱自定义阈值 > 邮箱所有者的用户阈值 > 系统配置；自定义阈值不超过系统上限。
+// user、mailbox 均可为空（匿名邮箱、分发列表）。
+func (f *Filter) Thresholds(user *domain.User, mailbox *domain.Mailbox) Thresholds {
+	t := f.thresholds
+	if user != nil {
+		f.override(&t, user.SpamQuarantineScore, user.SpamRejectScore)
+	}
+	if mailbox != nil {
+		f.override(&t, mailbox.SpamQuarantineScore, mailbox.SpamRejectScore)
+	}
+	return t
+}
+
+func (f *Filter) override(t *Thresholds, quarantine, reject *float64) {
+	if quarantine != nil && *quarantine > 0 {
+		t.Quarantine = min(*quarantine, f.maxScore)
+	}
+	if reject != nil && *reject > 0 {
+		t.Reject = min(*reject, f.maxScore)
+	}
+}
+
+// Record 记录一次评分的处理结果
+func (f *Filter) Record(verdict Verdict) {
+	f.record(string(verdict))
+}
+
+func (f *Filter) record(outcome string) {
+	if f.metrics != nil {
+		f.metrics.RecordSpamCheck(outcome)
+	}
+}
+
+func orDefault(value, fallback float64) float64 {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,133 @@
+package spam
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(config.SpamConfig{Provider: "off"})
+	assert.ErrorIs(t, err, ErrNotConfigured)
+
+	_, err = New(config.SpamConfig{})
+	assert.ErrorIs(t, err, ErrNotConfigured)
+
+	_, err = New(config.SpamConfig{Provider: "rspamd"})
+	assert.ErrorIs(t, err, ErrMissingAddress)
+
+	_, err = New(config.SpamConfig{Provider: "spamassassin", Address: "localhost:783"})
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+
+	filter, err := New(config.SpamConfig{Provider: "rspamd", Address: "localhost:11333"})
+	require.NoError(t, err)
+	assert.Equal(t, ProviderRspamd, filter.Name())
+}
+
+func TestThresholds(t *testing.T) {
+	filter := NewFilter(&Fake{}, config.SpamConfig{QuarantineScore: 6, RejectScore: 15, MaxMailboxScore: 50})
+
+	t.Run("按阈值分级", func(t *testing.T) {
+		th := filter.Thresholds(nil, nil)
+		assert.Equal(t, VerdictDeliver, th.Verdict(5.9))
+		assert.Equal(t, VerdictQuarantine, th.Verdict(6))
+		assert.Equal(t, VerdictQuarantine, th.Verdict(14.9))
+		assert.Equal(t, VerdictReject, th.Verdict(15))
+	})
+
+	t.Run("邮箱自定义阈值不超过系统上限", func(t *testing.T) {
+		quarantine, reject := 30.0, 500.0
+		th := filter.Thresholds(nil, &domain.Mailbox{SpamQuarantineScore: &quarantine, SpamRejectScore: &reject})
+		assert.Equal(t, Thresholds{Quarantine: 30, Reject: 50}, th)
+		assert.Equal(t, VerdictDeliver, th.Verdict(20))
+	})
+
+	t.Run("用户阈值作为邮箱的默认值", func(t *testing.T) {
+		userQuarantine, userReject, mailboxReject := 10.0, 20.0, 40.0
+		user := &domain.User{SpamQuarantineScore: &userQuarantine, SpamRejectScore: &userReject}
+		assert.Equal(t, Thresholds{Quarantine: 10, Reject: 20}, filter.Thresholds(user, nil))
+		th := filter.Thresholds(user, &domain.Mailbox{SpamRejectScore: &mailboxReject})
+		assert.Equal(t, Thresholds{Quarantine: 10, Reject: 40}, th)
+	})
+
+	t.Run("未配置时使用默认阈值", func(t *testing.T) {
+		th := NewFilter(&Fake{}, config.SpamConfig{}).Thresholds(nil, nil)
+		assert.Equal(t, Thresholds{Quarantine: DefaultQuarantineScore, Reject: DefaultRejectScore}, th)
+	})
+}
+
+func TestRspamd(t *testing.T) {
+	raw := "From: a@example.org\r\nSubject: hi\r\n\r\nbody\r\n"
+
+	t.Run("解析评分结果", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/checkv2", r.URL.Path)
+			assert.Equal(t, "sender@example.org", r.Header.Get("From"))
+			assert.Equal(t, []string{"a@corp.example", "b@corp.example"}, r.Header.Values("Rcpt"))
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Equal(t, raw, string(body))
+			_, _ = w.Write([]byte(`{"score":7.25,"required_score":15,"action":"add header",
+				"symbols":{"MISSING_DATE":{"name":"MISSING_DATE","score":1},"BAYES_SPAM":{"name":"BAYES_SPAM","score":5.1}}}`))
+		}))
+		defer server.Close()
+
+		filter := NewFilter(NewRspamd(server.URL), config.SpamConfig{Timeout: time.Second})
+		result, err := filter.Check(strings.NewReader(raw), Envelope{From: "sender@example.org", Recipients: []string{"a@corp.example", "b@corp.example"}})
+		require.NoError(t, err)
+		assert.Equal(t, &Result{Score: 7.25, Action: "add header", Symbols: []string{"BAYES_SPAM", "MISSING_DATE"}}, result)
+	})
+
+	t.Run("服务端错误按 fail-closed 返回不可用", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		filter := NewFilter(NewRspamd(server.URL), config.SpamConfig{Timeout: time.Second})
+		_, err := filter.Check(strings.NewReader(raw), Envelope{})
+		assert.ErrorIs(t, err, ErrUnavailable)
+	})
+
+	t.Run("超时", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		metrics := &recordingMetrics{}
+		closed := NewFilter(NewRspamd(server.URL), config.SpamConfig{Timeout: 20 * time.Millisecond})
+		closed.SetMetrics(metrics)
+		start := time.Now()
+		_, err := closed.Check(strings.NewReader(raw), Envelope{})
+		assert.ErrorIs(t, err, ErrUnavailable)
+		assert.Less(t, time.Since(start), time.Second)
+
+		open := NewFilter(NewRspamd(server.URL), config.SpamConfig{Timeout: 20 * time.Millisecond, FailOpen: true})
+		open.SetMetrics(metrics)
+		result, err := open.Check(strings.NewReader(raw), Envelope{})
+		assert.NoError(t, err)
+		assert.Nil(t, result, "fail-open 时不附分数")
+
+		assert.Equal(t, []string{OutcomeUnavailable, OutcomeUnavailable}, metrics.outcomes)
+	})
+}
+
+type recordingMetrics struct {
+	outcomes []string
+}
+
+func (m *recordingMetrics) RecordSpamCheck(outcome string) {
+	m.outcomes = append(m.outcomes, outcome)
+}
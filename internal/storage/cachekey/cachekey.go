@@ -0,0 +1,148 @@
+// Package cachekey 集中定义缓存键
+//
+// 所有缓存键都由本包构造：Redis 缓存按这些键读写，混合存储按同一组键失效，
+// 避免读写两端各自拼字符串导致失效遗漏。键的格式与历史版本保持一致，升级后已有缓存仍然有效。
+package cachekey
+
+import "strings"
+
+// Key 缓存键
+type Key string
+
+// String 返回键的字符串形式
+func (k Key) String() string {
+	return string(k)
+}
+
+// Namespace 返回键的命名空间
+func (k Key) Namespace() Namespace {
+	namespace, _, _ := strings.Cut(string(k), ":")
+	return Namespace(namespace)
+}
+
+// Namespace 缓存键命名空间（键的第一段，同一实体的键共享命名空间）
+type Namespace string
+
+// 命名空间
+const (
+	NamespaceMailbox          Namespace = "mailbox"
+	NamespaceMailboxSummaries Namespace = "mailbox_summaries"
+	NamespaceMailboxAddress   Namespace = "mailbox_address"
+	NamespaceMessage          Namespace = "message"
+	NamespaceMessageList      Namespace = "messages"
+	NamespaceMessageStats     Namespace = "message_stats"
+	NamespaceUser             Namespace = "user"
+	NamespaceAPIKey           Namespace = "apikey"
+	NamespaceSystemDomain     Namespace = "system_domain"
+	NamespaceSystemDomains    Namespace = "system_domains"
+	NamespaceSystemDomainName Namespace = "system_domain_name"
+	NamespaceSystem           Namespace = "system"
+	NamespaceBlacklist        Namespace = "blacklist"
+	NamespaceSession          Namespace = "session"
+	NamespaceNotFound         Namespace = "notfound"
+)
+
+// Key 在命名空间下构造键（各段以冒号连接）
+func (n Namespace) Key(parts ...string) Key {
+	return Key(string(n) + ":" + strings.Join(parts, ":"))
+}
+
+// Mailbox 邮箱实体
+func Mailbox(mailboxID string) Key {
+	return NamespaceMailbox.Key(mailboxID)
+}
+
+// MailboxSummaries 用户的邮箱摘要列表
+func MailboxSummaries(userID string) Key {
+	return NamespaceMailboxSummaries.Key(userID)
+}
+
+// MailboxAddress 按地址查询邮箱（只用于负缓存）
+func MailboxAddress(address string) Key {
+	return NamespaceMailboxAddress.Key(address)
+}
+
+// Message 单封邮件
+func Message(mailboxID, messageID string) Key {
+	return NamespaceMessage.Key(mailboxID, messageID)
+}
+
+// MessagePattern 邮箱下全部单封邮件的匹配模式（用于 SCAN，不能直接读写）
+func MessagePattern(mailboxID string) Key {
+	return NamespaceMessage.Key(mailboxID, "*")
+}
+
+// MessageList 邮箱的邮件列表
+func MessageList(mailboxID string) Key {
+	return NamespaceMessageList.Key(mailboxID)
+}
+
+// MessageStats 邮件统计（digest 由调用方根据查询条件计算）
+func MessageStats(digest string) Key {
+	return NamespaceMessageStats.Key(digest)
+}
+
+// User 用户实体
+func User(userID string) Key {
+	return NamespaceUser.Key(userID)
+}
+
+// UserEmail 按邮箱查询用户
+func UserEmail(email string) Key {
+	return NamespaceUser.Key("email", email)
+}
+
+// APIKey API Key 实体
+func APIKey(apiKeyID string) Key {
+	return NamespaceAPIKey.Key(apiKeyID)
+}
+
+// APIKeyUser API Key 字符串到用户 ID 的映射
+func APIKeyUser(apiKey string) Key {
+	return NamespaceAPIKey.Key("user", apiKey)
+}
+
+// SystemDomain 系统域名实体
+func SystemDomain(domainID string) Key {
+	return NamespaceSystemDomain.Key(domainID)
+}
+
+// DefaultSystemDomain 默认系统域名
+func DefaultSystemDomain() Key {
+	return NamespaceSystemDomain.Key("default")
+}
+
+// SystemDomainList 系统域名列表
+func SystemDomainList() Key {
+	return NamespaceSystemDomains.Key("list")
+}
+
+// SystemDomainName 按域名查询系统域名（只用于负缓存）
+func SystemDomainName(domainName string) Key {
+	return NamespaceSystemDomainName.Key(domainName)
+}
+
+// SystemConfig 系统配置
+func SystemConfig() Key {
+	return NamespaceSystem.Key("config")
+}
+
+// SystemStatistics 系统统计
+func SystemStatistics() Key {
+	return NamespaceSystem.Key("statistics")
+}
+
+// Blacklist JWT 黑名单
+func Blacklist(jti string) Key {
+	return NamespaceBlacklist.Key(jti)
+}
+
+// Session 用户会话
+func Session(sessionID string) Key {
+	return NamespaceSession.Key(sessionID)
+}
+
+// NotFound 负缓存记录（独立命名空间，不会与正常缓存键冲突）
+func NotFound(key Key) Key {
+	return NamespaceNotFound.Key(string(key))
+}
@@ -0,0 +1,36 @@
+package cachekey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 键格式与历史版本一致，升级后已有的 Redis 缓存仍能命中
+func TestKeys_MatchLegacyFormat(t *testing.T) {
+	cases := map[Key]string{
+		Mailbox("mb-1"):               "mailbox:mb-1",
+		MailboxSummaries("u-1"):       "mailbox_summaries:u-1",
+		MailboxAddress("a@temp.mail"): "mailbox_address:a@temp.mail",
+		Message("mb-1", "msg-1"):      "message:mb-1:msg-1",
+		MessagePattern("mb-1"):        "message:mb-1:*",
+		MessageList("mb-1"):           "messages:mb-1",
+		MessageStats("abc:1:2:5"):     "message_stats:abc:1:2:5",
+		User("u-1"):                   "user:u-1",
+		UserEmail("a@b.c"):            "user:email:a@b.c",
+		APIKey("k-1"):                 "apikey:k-1",
+		APIKeyUser("hash"):            "apikey:user:hash",
+		SystemDomain("sd-1"):          "system_domain:sd-1",
+		DefaultSystemDomain():         "system_domain:default",
+		SystemDomainList():            "system_domains:list",
+		SystemDomainName("temp.mail"): "system_domain_name:temp.mail",
+		SystemConfig():                "system:config",
+		SystemStatistics():            "system:statistics",
+		Blacklist("jti"):              "blacklist:jti",
+		Session("s-1"):                "session:s-1",
+		NotFound(MailboxAddress("x")): "notfound:mailbox_address:x",
+	}
+	for key, want := range cases {
+		assert.Equal(t, want, key.String())
+	}
+}
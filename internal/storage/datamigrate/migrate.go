@@ -0,0 +1,434 @@
+// Package datamigrate 将内存存储快照写入数据库存储（从开发模式平滑迁移）
+package datamigrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// Target 迁移使用的目标存储方法（postgres.Store 满足，mysql/sqlite 同）
+type Target interface {
+	CreateUser(ctx context.Context, user *domain.User) error
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*domain.User, error)
+	SaveAPIKey(ctx context.Context, apiKey *domain.APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error)
+	SaveSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error
+	GetSystemDomain(ctx context.Context, id string) (*domain.SystemDomain, error)
+	GetSystemDomainByDomain(ctx context.Context, name string) (*domain.SystemDomain, error)
+	SaveUserDomain(ctx context.Context, userDomain *domain.UserDomain) error
+	GetUserDomain(ctx context.Context, id string) (*domain.UserDomain, error)
+	GetUserDomainByDomain(ctx context.Context, name string) (*domain.UserDomain, error)
+	SaveMailbox(ctx context.Context, mailbox *domain.Mailbox) error
+	GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error)
+	GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error)
+	SaveAlias(ctx context.Context, alias *domain.MailboxAlias) error
+	GetAlias(ctx context.Context, id string) (*domain.MailboxAlias, error)
+	GetAliasByAddress(ctx context.Context, address string) (*domain.MailboxAlias, error)
+	SaveMessage(ctx context.Context, message *domain.Message) error
+	GetMessage(ctx context.Context, mailboxID, messageID string) (*domain.Message, error)
+	CreateWebhook(ctx context.Context, webhook *domain.Webhook) error
+	GetWebhook(ctx context.Context, id string) (*domain.Webhook, error)
+	CreateTag(ctx context.Context, tag *domain.Tag) error
+	GetTag(ctx context.Context, id string) (*domain.Tag, error)
+	GetTagByName(ctx context.Context, userID, name string) (*domain.Tag, error)
+	AddMessageTag(ctx context.Context, messageID, tagID string) error
+	GetMessageTags(ctx context.Context, messageID string) ([]domain.Tag, error)
+}
+
+// 实体类别（按写入顺序）
+const (
+	EntityUsers         = "users"
+	EntityAPIKeys       = "api_keys"
+	EntitySystemDomains = "system_domains"
+	EntityUserDomains   = "user_domains"
+	EntityMailboxes     = "mailboxes"
+	EntityAliases       = "aliases"
+	EntityMessages      = "messages"
+	EntityWebhooks      = "webhooks"
+	EntityTags          = "tags"
+	EntityMessageTags   = "message_tags"
+)
+
+// Options 迁移选项
+type Options struct {
+	DryRun bool             // 只查询目标库并统计，不写入
+	Now    func() time.Time // 判断邮箱是否已过期（为空时使用 time.Now）
+}
+
+// Counts 单个实体类别的统计
+type Counts struct {
+	Entity    string `json:"entity"`
+	Total     int    `json:"total"`
+	Created   int    `json:"created"`   // 已写入（dry-run 时为将要写入）
+	Skipped   int    `json:"skipped"`   // 目标库中已存在相同 ID，或邮箱已过期
+	Conflicts int    `json:"conflicts"` // 与目标库中的其他记录冲突，或依赖的记录未迁移
+	Failed    int    `json:"failed"`    // 写入出错
+}
+
+// Issue 单条记录的冲突或失败
+type Issue struct {
+	Entity string `json:"entity"`
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+	Failed bool   `json:"failed,omitempty"` // false 为冲突
+}
+
+// Report 迁移结果
+type Report struct {
+	DryRun   bool      `json:"dryRun"`
+	Entities []*Counts `json:"entities"`
+	Issues   []Issue   `json:"issues,omitempty"`
+}
+
+// HasFailures 是否有写入失败的记录（冲突不算失败）
+func (r *Report) HasFailures() bool {
+	for _, c := range r.Entities {
+		if c.Failed > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Print 以表格形式输出统计和每条冲突
+func (r *Report) Print(w io.Writer) {
+	if r.DryRun {
+		fmt.Fprintln(w, "dry run: nothing was written")
+	}
+	fmt.Fprintf(w, "%-16s %8s %8s %8s %10s %8s\n", "entity", "total", "created", "skipped", "conflicts", "failed")
+	for _, c := range r.Entities {
+		fmt.Fprintf(w, "%-16s %8d %8d %8d %10d %8d\n", c.Entity, c.Total, c.Created, c.Skipped, c.Conflicts, c.Failed)
+	}
+	for _, issue := range r.Issues {
+		kind := "conflict"
+		if issue.Failed {
+			kind = "failed"
+		}
+		fmt.Fprintf(w, "%s %s %s: %s\n", kind, issue.Entity, issue.ID, issue.Reason)
+	}
+}
+
+// migration 单次迁移的状态
+type migration struct {
+	target Target
+	opts   Options
+	report *Report
+
+	// 未迁移的记录，依赖它们的记录也不迁移
+	expiredMailboxes map[string]bool // 已过期，其下的记录一并跳过
+	blockedUsers     map[string]bool
+	blockedMailboxes map[string]bool
+	blockedMessages  map[string]bool
+	blockedTags      map[string]bool
+}
+
+// Migrate 按依赖顺序将快照写入目标存储
+//
+// 顺序：用户 → API Key → 系统域名 → 用户域名 → 邮箱 → 别名 → 邮件元数据 → Webhook → 标签 → 邮件标签。
+// 目标库中已存在相同 ID 的记录跳过，可重复执行；与已有记录冲突（如邮箱地址已被其他用户注册）
+// 按记录报告，依赖该记录的数据一并跳过。邮件正文和附件保存在文件系统存储中，不在此迁移。
+// 组织、分发列表、脱敏副本和系统配置不迁移。
+func Migrate(ctx context.Context, snap *memory.Snapshot, target Target, opts Options) *Report {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	m := &migration{
+		target:           target,
+		opts:             opts,
+		report:           &Report{DryRun: opts.DryRun},
+		expiredMailboxes: make(map[string]bool),
+		blockedUsers:     make(map[string]bool),
+		blockedMailboxes: make(map[string]bool),
+		blockedMessages:  make(map[string]bool),
+		blockedTags:      make(map[string]bool),
+	}
+
+	m.users(ctx, snap.Users)
+	m.apiKeys(ctx, snap.APIKeys)
+	m.systemDomains(ctx, snap.SystemDomains)
+	m.userDomains(ctx, snap.UserDomains)
+	m.mailboxes(ctx, snap.Mailboxes)
+	m.aliases(ctx, snap.Aliases)
+	m.messages(ctx, snap.Messages)
+	m.webhooks(ctx, snap.Webhooks)
+	m.tags(ctx, snap.Tags)
+	m.messageTags(ctx, snap.MessageTags)
+
+	return m.report
+}
+
+func (m *migration) counts(entity string, total int) *Counts {
+	c := &Counts{Entity: entity, Total: total}
+	m.report.Entities = append(m.report.Entities, c)
+	return c
+}
+
+func (m *migration) conflict(c *Counts, id, reason string) {
+	c.Conflicts++
+	m.report.Issues = append(m.report.Issues, Issue{Entity: c.Entity, ID: id, Reason: reason})
+}
+
+// write 执行写入（dry-run 时不执行），返回是否成功
+func (m *migration) write(c *Counts, id string, fn func() error) bool {
+	if !m.opts.DryRun {
+		if err := fn(); err != nil {
+			c.Failed++
+			m.report.Issues = append(m.report.Issues, Issue{Entity: c.Entity, ID: id, Reason: err.Error(), Failed: true})
+			return false
+		}
+	}
+	c.Created++
+	return true
+}
+
+func (m *migration) users(ctx context.Context, users []memory.SnapshotUser) {
+	c := m.counts(EntityUsers, len(users))
+	for _, entry := range users {
+		if entry.User == nil {
+			continue
+		}
+		user := *entry.User
+		user.PasswordHash = entry.PasswordHash
+		user.RecentLoginIPs = entry.RecentLoginIPs
+		user.TOTPSecret = entry.TOTPSecret
+		user.TOTPLastStep = entry.TOTPLastStep
+		user.RecoveryCodes = entry.RecoveryCodes
+		user.AppPasswordGeneration = entry.AppPasswordGeneration
+
+		if existing, err := m.target.GetUserByID(ctx, user.ID); err == nil && existing != nil {
+			c.Skipped++
+			continue
+		}
+		if existing, err := m.target.GetUserByEmail(ctx, user.Email); err == nil && existing != nil {
+			m.blockedUsers[user.ID] = true
+			m.conflict(c, user.ID, fmt.Sprintf("email %s already registered", user.Email))
+			continue
+		}
+		if existing, err := m.target.GetUserByUsername(ctx, user.Username); err == nil && existing != nil {
+			m.blockedUsers[user.ID] = true
+			m.conflict(c, user.ID, fmt.Sprintf("username %s already taken", user.Username))
+			continue
+		}
+		if !m.write(c, user.ID, func() error { return m.target.CreateUser(ctx, &user) }) {
+			m.blockedUsers[user.ID] = true
+		}
+	}
+}
+
+// ownerBlocked 所属用户未迁移
+func (m *migration) ownerBlocked(c *Counts, id string, userID *string) bool {
+	if userID == nil || !m.blockedUsers[*userID] {
+		return false
+	}
+	m.conflict(c, id, fmt.Sprintf("owner %s was not migrated", *userID))
+	return true
+}
+
+func (m *migration) apiKeys(ctx context.Context, keys []*domain.APIKey) {
+	c := m.counts(EntityAPIKeys, len(keys))
+	for _, k := range keys {
+		key := *k
+		if m.ownerBlocked(c, key.ID, &key.UserID) {
+			continue
+		}
+		if existing, err := m.target.GetAPIKey(ctx, key.ID); err == nil && existing != nil {
+			c.Skipped++
+			continue
+		}
+		m.write(c, key.ID, func() error { return m.target.SaveAPIKey(ctx, &key) })
+	}
+}
+
+func (m *migration) systemDomains(ctx context.Context, domains []*domain.SystemDomain) {
+	c := m.counts(EntitySystemDomains, len(domains))
+	for _, d := range domains {
+		sysDomain := *d
+		if existing, err := m.target.GetSystemDomain(ctx, sysDomain.ID); err == nil && existing != nil {
+			c.Skipped++
+			continue
+		}
+		if existing, err := m.target.GetSystemDomainByDomain(ctx, sysDomain.Domain); err == nil && existing != nil {
+			m.conflict(c, sysDomain.ID, fmt.Sprintf("domain %s already exists", sysDomain.Domain))
+			continue
+		}
+		m.write(c, sysDomain.ID, func() error { return m.target.SaveSystemDomain(ctx, &sysDomain) })
+	}
+}
+
+func (m *migration) userDomains(ctx context.Context, domains []*domain.UserDomain) {
+	c := m.counts(EntityUserDomains, len(domains))
+	for _, d := range domains {
+		userDomain := *d
+		if m.ownerBlocked(c, userDomain.ID, &userDomain.UserID) {
+			continue
+		}
+		if existing, err := m.target.GetUserDomain(ctx, userDomain.ID); err == nil && existing != nil {
+			c.Skipped++
+			continue
+		}
+		if existing, err := m.target.GetUserDomainByDomain(ctx, userDomain.Domain); err == nil && existing != nil {
+			m.conflict(c, userDomain.ID, fmt.Sprintf("domain %s already exists", userDomain.Domain))
+			continue
+		}
+		m.write(c, userDomain.ID, func() error { return m.target.SaveUserDomain(ctx, &userDomain) })
+	}
+}
+
+func (m *migration) mailboxes(ctx context.Context, mailboxes []memory.SnapshotMailbox) {
+	c := m.counts(EntityMailboxes, len(mailboxes))
+	now := m.opts.Now()
+	for _, entry := range mailboxes {
+		if entry.Mailbox == nil {
+			continue
+		}
+		mb := *entry.Mailbox
+		mb.IPSource = entry.IPSource
+		mb.IdleShortened = entry.IdleShortened
+		mb.IdleOriginalExpiresAt = entry.IdleOriginalExpiresAt
+
+		if m.ownerBlocked(c, mb.ID, mb.UserID) {
+			m.blockedMailboxes[mb.ID] = true
+			continue
+		}
+		// 已过期的邮箱会被目标实例立即清理，不迁移
+		if mb.ExpiresAt != nil && !mb.ExpiresAt.After(now) {
+			m.expiredMailboxes[mb.ID] = true
+			c.Skipped++
+			continue
+		}
+		if existing, err := m.target.GetMailbox(ctx, mb.ID); err == nil && existing != nil {
+			c.Skipped++
+			continue
+		}
+		if existing, err := m.target.GetMailboxByAddress(ctx, mb.Address); err == nil && existing != nil {
+			m.blockedMailboxes[mb.ID] = true
+			m.conflict(c, mb.ID, fmt.Sprintf("address %s already exists", mb.Address))
+			continue
+		}
+		// 计数由写入邮件时累加
+		mb.TotalCount, mb.Unread = 0, 0
+		if !m.write(c, mb.ID, func() error { return m.target.SaveMailbox(ctx, &mb) }) {
+			m.blockedMailboxes[mb.ID] = true
+		}
+	}
+}
+
+// mailboxBlocked 所属邮箱未迁移（已过期的跳过，其他按冲突报告）
+func (m *migration) mailboxBlocked(c *Counts, id, mailboxID string) bool {
+	switch {
+	case m.expiredMailboxes[mailboxID]:
+		c.Skipped++
+	case m.blockedMailboxes[mailboxID]:
+		m.conflict(c, id, fmt.Sprintf("mailbox %s was not migrated", mailboxID))
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *migration) aliases(ctx context.Context, aliases []*domain.MailboxAlias) {
+	c := m.counts(EntityAliases, len(aliases))
+	for _, a := range aliases {
+		alias := *a
+		if m.mailboxBlocked(c, alias.ID, alias.MailboxID) {
+			continue
+		}
+		if existing, err := m.target.GetAlias(ctx, alias.ID); err == nil && existing != nil {
+			c.Skipped++
+			continue
+		}
+		if existing, err := m.target.GetAliasByAddress(ctx, alias.Address); err == nil && existing != nil {
+			m.conflict(c, alias.ID, fmt.Sprintf("alias %s already exists", alias.Address))
+			continue
+		}
+		m.write(c, alias.ID, func() error { return m.target.SaveAlias(ctx, &alias) })
+	}
+}
+
+func (m *migration) messages(ctx context.Context, messages []memory.SnapshotMessage) {
+	c := m.counts(EntityMessages, len(messages))
+	for _, entry := range messages {
+		if entry.Message == nil {
+			continue
+		}
+		msg := *entry.Message
+		if m.mailboxBlocked(c, msg.ID, msg.MailboxID) {
+			m.blockedMessages[msg.ID] = true
+			continue
+		}
+		if existing, err := m.target.GetMessage(ctx, msg.MailboxID, msg.ID); err == nil && existing != nil {
+			c.Skipped++
+			continue
+		}
+		if !m.write(c, msg.ID, func() error { return m.target.SaveMessage(ctx, &msg) }) {
+			m.blockedMessages[msg.ID] = true
+		}
+	}
+}
+
+func (m *migration) webhooks(ctx context.Context, webhooks []*domain.Webhook) {
+	c := m.counts(EntityWebhooks, len(webhooks))
+	for _, w := range webhooks {
+		webhook := *w
+		if m.ownerBlocked(c, webhook.ID, &webhook.UserID) {
+			continue
+		}
+		if existing, err := m.target.GetWebhook(ctx, webhook.ID); err == nil && existing != nil {
+			c.Skipped++
+			continue
+		}
+		m.write(c, webhook.ID, func() error { return m.target.CreateWebhook(ctx, &webhook) })
+	}
+}
+
+func (m *migration) tags(ctx context.Context, tags []*domain.Tag) {
+	c := m.counts(EntityTags, len(tags))
+	for _, t := range tags {
+		tag := *t
+		if m.ownerBlocked(c, tag.ID, &tag.UserID) {
+			m.blockedTags[tag.ID] = true
+			continue
+		}
+		if existing, err := m.target.GetTag(ctx, tag.ID); err == nil && existing != nil {
+			c.Skipped++
+			continue
+		}
+		if existing, err := m.target.GetTagByName(ctx, tag.UserID, tag.Name); err == nil && existing != nil {
+			m.blockedTags[tag.ID] = true
+			m.conflict(c, tag.ID, fmt.Sprintf("tag %q already exists for the user", tag.Name))
+			continue
+		}
+		if !m.write(c, tag.ID, func() error { return m.target.CreateTag(ctx, &tag) }) {
+			m.blockedTags[tag.ID] = true
+		}
+	}
+}
+
+func (m *migration) messageTags(ctx context.Context, messageTags []*domain.MessageTag) {
+	c := m.counts(EntityMessageTags, len(messageTags))
+	for _, mt := range messageTags {
+		id := mt.MessageID + ":" + mt.TagID
+		if m.blockedTags[mt.TagID] {
+			m.conflict(c, id, fmt.Sprintf("tag %s was not migrated", mt.TagID))
+			continue
+		}
+		if m.blockedMessages[mt.MessageID] {
+			m.conflict(c, id, fmt.Sprintf("message %s was not migrated", mt.MessageID))
+			continue
+		}
+		if existing, err := m.target.GetMessageTags(ctx, mt.MessageID); err == nil &&
+			slices.ContainsFunc(existing, func(t domain.Tag) bool { return t.ID == mt.TagID }) {
+			c.Skipped++
+			continue
+		}
+		m.write(c, id, func() error { return m.target.AddMessageTag(ctx, mt.MessageID, mt.TagID) })
+	}
+}
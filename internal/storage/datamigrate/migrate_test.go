@@ -0,0 +1,242 @@
+package datamigrate
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+	"tempmail/backend/internal/storage/postgres"
+)
+
+// seedSource 源内存存储：alice 的完整数据、与目标库邮箱冲突的 bob、即将过期的游客邮箱
+func seedSource(t *testing.T, now time.Time) *memory.Store {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	created := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	longLived := now.Add(48 * time.Hour).UTC().Truncate(time.Second)
+	shortLived := now.Add(30 * time.Minute).UTC().Truncate(time.Second)
+	alice, bob := "user-alice", "user-bob"
+
+	for _, user := range []*domain.User{
+		{ID: alice, Email: "alice@example.com", Username: "alice", PasswordHash: "hash-alice", Role: domain.RoleUser, Tier: domain.TierPro, IsActive: true, CreatedAt: created, UpdatedAt: created},
+		{ID: bob, Email: "bob@example.com", Username: "bob", PasswordHash: "hash-bob", Role: domain.RoleUser, Tier: domain.TierFree, IsActive: true, CreatedAt: created, UpdatedAt: created},
+	} {
+		require.NoError(t, store.CreateUser(t.Context(), user))
+	}
+	require.NoError(t, store.SaveAPIKey(t.Context(), &domain.APIKey{ID: "key-alice", UserID: alice, Key: "hash-key-alice", KeyPrefix: "tm_a", Name: "ci", IsActive: true, CreatedAt: created}))
+	require.NoError(t, store.SaveSystemDomain(t.Context(), &domain.SystemDomain{ID: "sd-1", Domain: "temp.mail", Status: domain.SystemDomainStatusVerified, VerifyMethod: "dns_txt", IsActive: true, CreatedAt: created}))
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{ID: "ud-alice", UserID: alice, Domain: "alice.dev", Status: domain.DomainStatusVerified, CreatedAt: created, UpdatedAt: created}))
+
+	for _, mb := range []*domain.Mailbox{
+		{ID: "mb-alice", Address: "alice-box@temp.mail", LocalPart: "alice-box", Domain: "temp.mail", Token: "tok-alice", UserID: &alice, CreatedAt: created, ExpiresAt: &longLived},
+		{ID: "mb-bob", Address: "bob-box@temp.mail", LocalPart: "bob-box", Domain: "temp.mail", Token: "tok-bob", UserID: &bob, CreatedAt: created, ExpiresAt: &longLived},
+		{ID: "mb-guest", Address: "guest@temp.mail", LocalPart: "guest", Domain: "temp.mail", Token: "tok-guest", CreatedAt: created, ExpiresAt: &shortLived},
+	} {
+		require.NoError(t, store.SaveMailbox(t.Context(), mb))
+	}
+	require.NoError(t, store.SaveAlias(t.Context(), &domain.MailboxAlias{ID: "alias-alice", MailboxID: "mb-alice", Address: "alice-alias@temp.mail", CreatedAt: created, IsActive: true}))
+
+	for _, msg := range []*domain.Message{
+		{ID: "msg-1", MailboxID: "mb-alice", From: "ci@example.com", To: "alice-box@temp.mail", Subject: "build passed", CreatedAt: created, ReceivedAt: created, Size: 120, HasText: true,
+			CapturedHeaders: map[string]string{"X-Test-Run-Id": "4711"}},
+		{ID: "msg-2", MailboxID: "mb-alice", From: "ci@example.com", Subject: "build failed", IsRead: true, CreatedAt: created, ReceivedAt: created.Add(time.Minute), SpamScore: 1.5},
+		{ID: "msg-bob", MailboxID: "mb-bob", Subject: "for bob", CreatedAt: created, ReceivedAt: created},
+		{ID: "msg-guest", MailboxID: "mb-guest", Subject: "for guest", CreatedAt: created, ReceivedAt: created},
+	} {
+		require.NoError(t, store.SaveMessage(t.Context(), msg))
+	}
+
+	webhook := &domain.Webhook{ID: "wh-alice", UserID: alice, URL: "https://hooks.example.com/a", Events: []string{"message.received"}, Secret: "s3cret", IsActive: true}
+	require.NoError(t, store.CreateWebhook(t.Context(), webhook))
+	webhook.CreatedAt, webhook.UpdatedAt = created, created
+	require.NoError(t, store.CreateWebhook(t.Context(), &domain.Webhook{ID: "wh-bob", UserID: bob, URL: "https://hooks.example.com/b", IsActive: true}))
+
+	tag := &domain.Tag{ID: "tag-ci", UserID: alice, Name: "ci", Color: "#00ff00"}
+	require.NoError(t, store.CreateTag(t.Context(), tag))
+	tag.CreatedAt, tag.UpdatedAt = created, created
+	require.NoError(t, store.AddMessageTag(t.Context(), "msg-1", "tag-ci"))
+	return store
+}
+
+// newTarget 目标 SQLite 库，已有一个使用 bob 邮箱注册的账号
+func newTarget(t *testing.T) *postgres.Store {
+	t.Helper()
+	target, err := postgres.NewSQLiteStore(filepath.Join(t.TempDir(), "tempmail.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { target.Close() })
+	require.NoError(t, target.CreateUser(t.Context(), &domain.User{ID: "user-existing", Email: "bob@example.com", Username: "bobby", Role: domain.RoleUser, Tier: domain.TierFree, IsActive: true}))
+	return target
+}
+
+func countsByEntity(report *Report) map[string]Counts {
+	result := make(map[string]Counts, len(report.Entities))
+	for _, c := range report.Entities {
+		result[c.Entity] = *c
+	}
+	return result
+}
+
+// assertSameJSON API 以 JSON 返回数据，按 JSON 比较两个存储的读取结果
+func assertSameJSON(t *testing.T, want, got any) {
+	t.Helper()
+	wantJSON, err := json.Marshal(want)
+	require.NoError(t, err)
+	gotJSON, err := json.Marshal(got)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(wantJSON), string(gotJSON))
+}
+
+func TestMigrate(t *testing.T) {
+	now := time.Now()
+	// 迁移时游客邮箱已过期
+	opts := Options{Now: func() time.Time { return now.Add(time.Hour) }}
+
+	t.Run("dry-run 只统计不写入", func(t *testing.T) {
+		source := seedSource(t, now)
+		target := newTarget(t)
+
+		dryOpts := opts
+		dryOpts.DryRun = true
+		report := Migrate(t.Context(), source.Snapshot(), target, dryOpts)
+		assert.True(t, report.DryRun)
+		assert.False(t, report.HasFailures())
+
+		counts := countsByEntity(report)
+		assert.Equal(t, Counts{Entity: EntityUsers, Total: 2, Created: 1, Conflicts: 1}, counts[EntityUsers])
+		assert.Equal(t, Counts{Entity: EntityMessages, Total: 4, Created: 2, Skipped: 1, Conflicts: 1}, counts[EntityMessages])
+
+		_, err := target.GetUserByID(t.Context(), "user-alice")
+		assert.Error(t, err)
+		_, err = target.GetMailbox(t.Context(), "mb-alice")
+		assert.Error(t, err)
+	})
+
+	t.Run("按依赖顺序写入，冲突按记录报告", func(t *testing.T) {
+		source := seedSource(t, now)
+		target := newTarget(t)
+
+		report := Migrate(t.Context(), source.Snapshot(), target, opts)
+		require.False(t, report.HasFailures(), "%+v", report.Issues)
+
+		counts := countsByEntity(report)
+		assert.Equal(t, Counts{Entity: EntityUsers, Total: 2, Created: 1, Conflicts: 1}, counts[EntityUsers])
+		assert.Equal(t, Counts{Entity: EntityAPIKeys, Total: 1, Created: 1}, counts[EntityAPIKeys])
+		assert.Equal(t, Counts{Entity: EntitySystemDomains, Total: 1, Created: 1}, counts[EntitySystemDomains])
+		assert.Equal(t, Counts{Entity: EntityUserDomains, Total: 1, Created: 1}, counts[EntityUserDomains])
+		assert.Equal(t, Counts{Entity: EntityMailboxes, Total: 3, Created: 1, Skipped: 1, Conflicts: 1}, counts[EntityMailboxes])
+		assert.Equal(t, Counts{Entity: EntityAliases, Total: 1, Created: 1}, counts[EntityAliases])
+		assert.Equal(t, Counts{Entity: EntityMessages, Total: 4, Created: 2, Skipped: 1, Conflicts: 1}, counts[EntityMessages])
+		assert.Equal(t, Counts{Entity: EntityWebhooks, Total: 2, Created: 1, Conflicts: 1}, counts[EntityWebhooks])
+		assert.Equal(t, Counts{Entity: EntityTags, Total: 1, Created: 1}, counts[EntityTags])
+		assert.Equal(t, Counts{Entity: EntityMessageTags, Total: 1, Created: 1}, counts[EntityMessageTags])
+
+		assert.Contains(t, report.Issues, Issue{Entity: EntityUsers, ID: "user-bob", Reason: "email bob@example.com already registered"})
+		assert.Contains(t, report.Issues, Issue{Entity: EntityMailboxes, ID: "mb-bob", Reason: "owner user-bob was not migrated"})
+
+		// 已有账号不受影响
+		existing, err := target.GetUserByEmail(t.Context(), "bob@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "user-existing", existing.ID)
+	})
+
+	t.Run("迁移后的 API 读取结果与源存储一致", func(t *testing.T) {
+		source := seedSource(t, now)
+		target := newTarget(t)
+		require.False(t, Migrate(t.Context(), source.Snapshot(), target, opts).HasFailures())
+
+		wantUser, err := source.GetUserByID(t.Context(), "user-alice")
+		require.NoError(t, err)
+		gotUser, err := target.GetUserByID(t.Context(), "user-alice")
+		require.NoError(t, err)
+		assertSameJSON(t, wantUser, gotUser)
+		assert.Equal(t, wantUser.PasswordHash, gotUser.PasswordHash)
+
+		wantMailbox, err := source.GetMailbox(t.Context(), "mb-alice")
+		require.NoError(t, err)
+		gotMailbox, err := target.GetMailbox(t.Context(), "mb-alice")
+		require.NoError(t, err)
+		assertSameJSON(t, wantMailbox, gotMailbox)
+
+		sourceMessages, targetMessages := service.NewMessageService(source), service.NewMessageService(target)
+		wantList, err := sourceMessages.List(t.Context(), "mb-alice")
+		require.NoError(t, err)
+		gotList, err := targetMessages.List(t.Context(), "mb-alice")
+		require.NoError(t, err)
+		assertSameJSON(t, wantList, gotList)
+
+		wantMsg, err := sourceMessages.Get(t.Context(), "mb-alice", "msg-1")
+		require.NoError(t, err)
+		gotMsg, err := targetMessages.Get(t.Context(), "mb-alice", "msg-1")
+		require.NoError(t, err)
+		assertSameJSON(t, wantMsg, gotMsg)
+
+		wantAlias, err := source.GetAliasByAddress(t.Context(), "alice-alias@temp.mail")
+		require.NoError(t, err)
+		gotAlias, err := target.GetAliasByAddress(t.Context(), "alice-alias@temp.mail")
+		require.NoError(t, err)
+		assertSameJSON(t, wantAlias, gotAlias)
+
+		wantTags, err := source.ListTags(t.Context(), "user-alice")
+		require.NoError(t, err)
+		gotTags, err := target.ListTags(t.Context(), "user-alice")
+		require.NoError(t, err)
+		assertSameJSON(t, wantTags, gotTags)
+
+		wantMessageTags, err := source.GetMessageTags(t.Context(), "msg-1")
+		require.NoError(t, err)
+		gotMessageTags, err := target.GetMessageTags(t.Context(), "msg-1")
+		require.NoError(t, err)
+		assertSameJSON(t, wantMessageTags, gotMessageTags)
+
+		wantWebhooks, err := source.ListWebhooks(t.Context(), "user-alice")
+		require.NoError(t, err)
+		gotWebhooks, err := target.ListWebhooks(t.Context(), "user-alice")
+		require.NoError(t, err)
+		assertSameJSON(t, wantWebhooks, gotWebhooks)
+
+		wantKey, err := source.GetAPIKey(t.Context(), "key-alice")
+		require.NoError(t, err)
+		gotKey, err := target.GetAPIKey(t.Context(), "key-alice")
+		require.NoError(t, err)
+		assertSameJSON(t, wantKey, gotKey)
+
+		wantDomain, err := source.GetSystemDomainByDomain(t.Context(), "temp.mail")
+		require.NoError(t, err)
+		gotDomain, err := target.GetSystemDomainByDomain(t.Context(), "temp.mail")
+		require.NoError(t, err)
+		assertSameJSON(t, wantDomain, gotDomain)
+
+		userDomain, err := target.GetUserDomainByDomain(t.Context(), "alice.dev")
+		require.NoError(t, err)
+		assert.Equal(t, "user-alice", userDomain.UserID)
+		assert.Equal(t, domain.DomainStatusVerified, userDomain.Status)
+	})
+
+	t.Run("重复执行只跳过已迁移的记录，邮箱计数不变", func(t *testing.T) {
+		source := seedSource(t, now)
+		target := newTarget(t)
+		snap := source.Snapshot()
+		require.False(t, Migrate(t.Context(), snap, target, opts).HasFailures())
+
+		report := Migrate(t.Context(), snap, target, opts)
+		require.False(t, report.HasFailures(), "%+v", report.Issues)
+		for _, c := range report.Entities {
+			assert.Zero(t, c.Created, c.Entity)
+		}
+		counts := countsByEntity(report)
+		assert.Equal(t, 1, counts[EntityUsers].Skipped)
+		assert.Equal(t, Counts{Entity: EntityMessages, Total: 4, Skipped: 3, Conflicts: 1}, counts[EntityMessages])
+
+		mailbox, err := target.GetMailbox(t.Context(), "mb-alice")
+		require.NoError(t, err)
+		assert.Equal(t, 2, mailbox.TotalCount)
+		assert.Equal(t, 1, mailbox.Unread)
+	})
+}
@@ -0,0 +1,337 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 内容寻址附件存储
+//
+// 相同内容的附件只保存一份：blobs/{hash[0:2]}/{hash[2:4]}/{hash}。
+// 引用关系保存在同级的 {hash}.refs/ 目录中，每个引用一个空文件（{messageID}_{attachmentID}），
+// 引用文件天然幂等，重复写入不会多计数。引用数归零时删除 blob。
+// 同一哈希的增减引用通过分段锁串行化，避免“删除最后一个引用”与“新写入”竞争。
+
+const blobsDirName = "blobs"
+
+// blobLockStripes 哈希分段锁数量（按哈希首字节取模）
+const blobLockStripes = 256
+
+// blobLocks 分段锁，按哈希前缀映射
+type blobLocks struct {
+	stripes [blobLockStripes]sync.Mutex
+}
+
+// forHash 返回哈希对应的锁
+func (l *blobLocks) forHash(hash string) *sync.Mutex {
+	b, err := hex.DecodeString(hash[:2])
+	if err != nil || len(b) == 0 {
+		return &l.stripes[0]
+	}
+	return &l.stripes[int(b[0])%blobLockStripes]
+}
+
+// attachmentMeta 附件旁路元数据（attachments/{safeFilename}.meta.json）
+type attachmentMeta struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256,omitempty"`
+	SavedAt     string `json:"savedAt"`
+}
+
+// hashContent 计算内容的 SHA-256
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// blobRef 生成引用名
+func blobRef(messageID, attachmentID string) string {
+	return messageID + "_" + attachmentID
+}
+
+// blobPath 获取 blob 文件路径
+func (s *Store) blobPath(hash string) string {
+	return filepath.Join(s.basePath, blobsDirName, hash[:2], hash[2:4], hash)
+}
+
+// blobRefsDir 获取 blob 引用目录
+func (s *Store) blobRefsDir(hash string) string {
+	return s.blobPath(hash) + ".refs"
+}
+
+// putBlob 写入 blob（不存在时）并登记引用
+//
+// 返回值 created 表示本次是否新建了物理文件。
+func (s *Store) putBlob(hash string, content []byte, ref string) (created bool, err error) {
+	lock := s.blobLocks.forHash(hash)
+	lock.Lock()
+	defer lock.Unlock()
+
+	path := s.blobPath(hash)
+	if err := os.MkdirAll(s.blobRefsDir(hash), 0755); err != nil {
+		return false, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		// 先写临时文件再重命名，避免读到半个 blob
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, content, 0644); err != nil {
+			return false, fmt.Errorf("failed to write blob: %w", err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+			return false, fmt.Errorf("failed to commit blob: %w", err)
+		}
+		created = true
+	}
+
+	if err := os.WriteFile(filepath.Join(s.blobRefsDir(hash), ref), nil, 0644); err != nil {
+		return created, fmt.Errorf("failed to write blob reference: %w", err)
+	}
+	return created, nil
+}
+
+// commitBlobFile 将已写好的临时文件提交为 blob（已存在时丢弃临时文件）并登记引用
+func (s *Store) commitBlobFile(hash, tmpPath, ref string) error {
+	lock := s.blobLocks.forHash(hash)
+	lock.Lock()
+	defer lock.Unlock()
+
+	path := s.blobPath(hash)
+	if err := os.MkdirAll(s.blobRefsDir(hash), 0755); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.Rename(tmpPath, path); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to commit blob: %w", err)
+		}
+	} else {
+		os.Remove(tmpPath)
+	}
+
+	if err := os.WriteFile(filepath.Join(s.blobRefsDir(hash), ref), nil, 0644); err != nil {
+		return fmt.Errorf("failed to write blob reference: %w", err)
+	}
+	return nil
+}
+
+// addBlobRef 为已存在的 blob 登记引用（流式暂存的附件使用）
+func (s *Store) addBlobRef(hash, ref string) error {
+	if len(hash) < 4 {
+		return fmt.Errorf("invalid blob hash")
+	}
+	lock := s.blobLocks.forHash(hash)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := os.Stat(s.blobPath(hash)); err != nil {
+		return fmt.Errorf("blob not found: %w", err)
+	}
+	if err := os.MkdirAll(s.blobRefsDir(hash), 0755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.blobRefsDir(hash), ref), nil, 0644); err != nil {
+		return fmt.Errorf("failed to write blob reference: %w", err)
+	}
+	return nil
+}
+
+// releaseBlob 移除引用，引用数归零时删除 blob
+func (s *Store) releaseBlob(hash, ref string) error {
+	if len(hash) < 4 {
+		return nil
+	}
+	lock := s.blobLocks.forHash(hash)
+	lock.Lock()
+	defer lock.Unlock()
+
+	refsDir := s.blobRefsDir(hash)
+	if err := os.Remove(filepath.Join(refsDir, ref)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove blob reference: %w", err)
+	}
+
+	if entries, err := os.ReadDir(refsDir); err == nil && len(entries) > 0 {
+		return nil
+	}
+
+	if err := os.Remove(s.blobPath(hash)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove blob: %w", err)
+	}
+	os.Remove(refsDir)
+	return nil
+}
+
+// blobRefCount 获取 blob 当前引用数
+func (s *Store) blobRefCount(hash string) int {
+	entries, err := os.ReadDir(s.blobRefsDir(hash))
+	if err != nil {
+		return 0
+	}
+	return len(entries)
+}
+
+// releaseMessageBlobs 释放邮件目录下全部附件的 blob 引用
+func (s *Store) releaseMessageBlobs(messagePath string) error {
+	messageID := filepath.Base(messagePath)
+	metas, err := filepath.Glob(filepath.Join(messagePath, "attachments", "*.meta.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, metaFile := range metas {
+		meta, err := readAttachmentMeta(metaFile)
+		if err != nil || meta.SHA256 == "" {
+			continue
+		}
+		if err := s.releaseBlob(meta.SHA256, blobRef(messageID, meta.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeMessageDir 释放引用后删除邮件目录
+func (s *Store) removeMessageDir(messagePath string) error {
+	if err := s.releaseMessageBlobs(messagePath); err != nil {
+		return err
+	}
+	return os.RemoveAll(messagePath)
+}
+
+// readAttachmentMeta 读取附件旁路元数据
+func readAttachmentMeta(path string) (*attachmentMeta, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var meta attachmentMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// writeAttachmentMeta 写入附件旁路元数据
+func writeAttachmentMeta(path string, meta *attachmentMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// CompactionReport 附件去重迁移报告
+type CompactionReport struct {
+	FilesScanned  int   `json:"filesScanned"`  // 扫描的附件数
+	FilesMigrated int   `json:"filesMigrated"` // 迁移到 blob 的附件数
+	BlobsCreated  int   `json:"blobsCreated"`  // 新建的 blob 数
+	BytesBefore   int64 `json:"bytesBefore"`   // 迁移前私有副本总字节
+	BytesAfter    int64 `json:"bytesAfter"`    // 新增 blob 总字节
+	BytesSaved    int64 `json:"bytesSaved"`    // 节省的字节
+}
+
+// CompactAttachments 将旧版按邮件保存的附件迁移为内容寻址 blob
+//
+// 扫描所有 attachments/*.meta.json，对尚未记录哈希的附件计算 SHA-256，
+// 写入 blob 并登记引用，回写旁路元数据和 metadata.json，最后删除私有副本。
+// 可重复执行，已迁移的附件会被跳过。
+func (s *Store) CompactAttachments() (*CompactionReport, error) {
+	report := &CompactionReport{}
+
+	metas, err := filepath.Glob(filepath.Join(s.basePath, "mails", "*", "*", "*", "attachments", "*.meta.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, metaFile := range metas {
+		report.FilesScanned++
+
+		meta, err := readAttachmentMeta(metaFile)
+		if err != nil || meta.SHA256 != "" {
+			continue
+		}
+
+		contentFile := strings.TrimSuffix(metaFile, ".meta.json")
+		content, err := os.ReadFile(contentFile)
+		if err != nil {
+			continue
+		}
+
+		messagePath := filepath.Dir(filepath.Dir(metaFile))
+		messageID := filepath.Base(messagePath)
+		hash := hashContent(content)
+
+		created, err := s.putBlob(hash, content, blobRef(messageID, meta.ID))
+		if err != nil {
+			return report, err
+		}
+
+		meta.SHA256 = hash
+		if err := writeAttachmentMeta(metaFile, meta); err != nil {
+			return report, fmt.Errorf("failed to rewrite attachment metadata: %w", err)
+		}
+		if err := s.rewriteMessageAttachment(messagePath, meta.ID, hash); err != nil {
+			return report, err
+		}
+		if err := os.Remove(contentFile); err != nil {
+			return report, fmt.Errorf("failed to remove legacy attachment: %w", err)
+		}
+
+		report.FilesMigrated++
+		report.BytesBefore += int64(len(content))
+		if created {
+			report.BlobsCreated++
+			report.BytesAfter += int64(len(content))
+		}
+	}
+
+	report.BytesSaved = report.BytesBefore - report.BytesAfter
+	return report, nil
+}
+
+// rewriteMessageAttachment 更新 metadata.json 中附件的存储路径和哈希
+func (s *Store) rewriteMessageAttachment(messagePath, attachmentID, hash string) error {
+	metaFile := filepath.Join(messagePath, "metadata.json")
+	data, err := os.ReadFile(metaFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse metadata: %w", err)
+	}
+
+	attachments, _ := raw["attachments"].([]interface{})
+	for _, item := range attachments {
+		att, ok := item.(map[string]interface{})
+		if !ok || att["id"] != attachmentID {
+			continue
+		}
+		att["sha256"] = hash
+		if rel, err := filepath.Rel(s.basePath, s.blobPath(hash)); err == nil {
+			att["storagePath"] = filepath.ToSlash(rel)
+		}
+	}
+
+	out, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaFile, out, 0644)
+}
@@ -0,0 +1,199 @@
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"tempmail/backend/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// saveMessageWithAttachment 保存带一个附件的邮件
+func saveMessageWithAttachment(t *testing.T, store *Store, mailboxID, messageID string, content []byte) *domain.Attachment {
+	t.Helper()
+	att := &domain.Attachment{
+		ID:          "att-" + messageID,
+		MessageID:   messageID,
+		Filename:    "newsletter.pdf",
+		ContentType: "application/pdf",
+		Size:        int64(len(content)),
+		Content:     content,
+	}
+	path, err := store.SaveAttachment(mailboxID, messageID, att.ID, att)
+	require.NoError(t, err)
+	att.StoragePath = path
+
+	_, err = store.SaveMessageMetadata(mailboxID, messageID, &domain.Message{
+		ID:          messageID,
+		MailboxID:   mailboxID,
+		Subject:     "Weekly",
+		CreatedAt:   time.Now(),
+		Attachments: []*domain.Attachment{att},
+	})
+	require.NoError(t, err)
+	return att
+}
+
+func TestContentAddressedAttachments(t *testing.T) {
+	store, tempDir := setupTestStore(t)
+	defer cleanupTestStore(t, tempDir)
+
+	content := []byte("%PDF-1.4 same newsletter body")
+	hash := hashContent(content)
+
+	t.Run("相同附件只保存一份", func(t *testing.T) {
+		for i := 1; i <= 3; i++ {
+			saveMessageWithAttachment(t, store, fmt.Sprintf("mb-%d", i), fmt.Sprintf("msg-%d", i), content)
+		}
+
+		blobs, err := filepath.Glob(filepath.Join(store.basePath, blobsDirName, "*", "*", hash))
+		require.NoError(t, err)
+		assert.Len(t, blobs, 1)
+		assert.Equal(t, 3, store.blobRefCount(hash))
+
+		att, err := store.GetAttachment("mb-2", "msg-2", "att-msg-2")
+		require.NoError(t, err)
+		assert.Equal(t, content, att.Content)
+
+		stats, err := store.GetStorageStats()
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats["blob_count"])
+		assert.Equal(t, int64(2*len(content)), stats["dedup_saved_bytes"])
+	})
+
+	t.Run("删除部分引用后 blob 保留，全部删除后清理", func(t *testing.T) {
+		require.NoError(t, store.DeleteMessage("mb-1", "msg-1"))
+		require.NoError(t, store.DeleteMailbox("mb-2"))
+
+		_, err := os.Stat(store.blobPath(hash))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, store.blobRefCount(hash))
+
+		att, err := store.GetAttachment("mb-3", "msg-3", "att-msg-3")
+		require.NoError(t, err)
+		assert.Equal(t, content, att.Content)
+
+		require.NoError(t, store.DeleteMessage("mb-3", "msg-3"))
+		_, err = os.Stat(store.blobPath(hash))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestContentAddressedAttachments_ConcurrentDeleteAndIngest(t *testing.T) {
+	store, tempDir := setupTestStore(t)
+	defer cleanupTestStore(t, tempDir)
+
+	content := []byte("shared attachment under contention")
+	hash := hashContent(content)
+
+	for round := 0; round < 20; round++ {
+		oldMsg := fmt.Sprintf("old-%d", round)
+		newMsg := fmt.Sprintf("new-%d", round)
+		saveMessageWithAttachment(t, store, "mb-race", oldMsg, content)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, store.DeleteMessage("mb-race", oldMsg))
+		}()
+		go func() {
+			defer wg.Done()
+			saveMessageWithAttachment(t, store, "mb-race", newMsg, content)
+		}()
+		wg.Wait()
+
+		// 新写入的邮件必须始终能读到附件
+		att, err := store.GetAttachment("mb-race", newMsg, "att-"+newMsg)
+		require.NoError(t, err, "round %d", round)
+		assert.Equal(t, content, att.Content)
+
+		require.NoError(t, store.DeleteMessage("mb-race", newMsg))
+		_, err = os.Stat(store.blobPath(hash))
+		assert.True(t, os.IsNotExist(err), "round %d", round)
+	}
+}
+
+func TestCompactAttachments(t *testing.T) {
+	store, tempDir := setupTestStore(t)
+	defer cleanupTestStore(t, tempDir)
+
+	content := []byte("legacy attachment content")
+
+	// 模拟迁移前的布局：每封邮件一份私有副本，旁路元数据没有哈希
+	for i := 1; i <= 2; i++ {
+		mailboxID, messageID := fmt.Sprintf("mb-%d", i), fmt.Sprintf("msg-%d", i)
+		attID := "att-" + messageID
+		attachDir := filepath.Join(store.getMessagePath(mailboxID, messageID), "attachments")
+		require.NoError(t, os.MkdirAll(attachDir, 0755))
+
+		legacyFile := filepath.Join(attachDir, store.generateSafeFilename(attID, "a.txt"))
+		require.NoError(t, os.WriteFile(legacyFile, content, 0644))
+		require.NoError(t, writeAttachmentMeta(legacyFile+".meta.json", &attachmentMeta{
+			ID: attID, Filename: "a.txt", ContentType: "text/plain", Size: int64(len(content)),
+		}))
+		_, err := store.SaveMessageMetadata(mailboxID, messageID, &domain.Message{
+			ID:        messageID,
+			MailboxID: mailboxID,
+			Attachments: []*domain.Attachment{
+				{ID: attID, MessageID: messageID, Filename: "a.txt", Size: int64(len(content))},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	report, err := store.CompactAttachments()
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.FilesMigrated)
+	assert.Equal(t, 1, report.BlobsCreated)
+	assert.Equal(t, int64(len(content)), report.BytesSaved)
+
+	meta, err := store.GetMessageMetadata("mb-1", "msg-1")
+	require.NoError(t, err)
+	assert.Equal(t, hashContent(content), meta.Attachments[0].SHA256)
+
+	att, err := store.GetAttachment("mb-2", "msg-2", "att-msg-2")
+	require.NoError(t, err)
+	assert.Equal(t, content, att.Content)
+
+	// 再次执行不会重复迁移
+	report, err = store.CompactAttachments()
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.FilesMigrated)
+}
+
+func TestAttachmentDetectedTypeInMetadata(t *testing.T) {
+	store, tempDir := setupTestStore(t)
+	defer cleanupTestStore(t, tempDir)
+
+	content := []byte("<html><script>alert(1)</script></html>")
+	att := &domain.Attachment{
+		ID:          "att-1",
+		MessageID:   "msg-1",
+		Filename:    "photo.png",
+		ContentType: "image/png",
+		Size:        int64(len(content)),
+		Content:     content,
+
+		DetectedContentType: "text/html",
+		ContentTypeMismatch: true,
+	}
+	_, err := store.SaveAttachment("mb-1", "msg-1", att.ID, att)
+	require.NoError(t, err)
+	_, err = store.SaveMessageMetadata("mb-1", "msg-1", &domain.Message{
+		ID: "msg-1", MailboxID: "mb-1", CreatedAt: time.Now(), Attachments: []*domain.Attachment{att},
+	})
+	require.NoError(t, err)
+
+	loaded, err := store.GetAttachment("mb-1", "msg-1", "att-1")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", loaded.ContentType)
+	assert.Equal(t, "text/html", loaded.DetectedContentType, "下载时按实际类型判断，不能退回声明类型")
+	assert.True(t, loaded.ContentTypeMismatch)
+}
@@ -41,7 +41,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 			Token:     "test-token",
 			CreatedAt: time.Now(),
 		}
-		err := memStore.SaveMailbox(mailbox)
+		err := memStore.SaveMailbox(t.Context(), mailbox)
 		require.NoError(t, err)
 
 		// 创建邮件（带完整内容）
@@ -67,14 +67,14 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 		}
 
 		// 创建邮件
-		message, err := msgService.Create(input)
+		message, err := msgService.Create(t.Context(), input)
 		require.NoError(t, err)
 		assert.NotEmpty(t, message.ID)
 		assert.Equal(t, mailbox.ID, message.MailboxID)
 
 		// 文件系统写入由 MessageService 完成，无需额外操作
 		// 读取邮件（应该从文件系统加载内容）
-		retrieved, err := msgService.Get(mailbox.ID, message.ID)
+		retrieved, err := msgService.Get(t.Context(), mailbox.ID, message.ID)
 		require.NoError(t, err)
 		assert.Equal(t, message.ID, retrieved.ID)
 		assert.Equal(t, input.Subject, retrieved.Subject)
@@ -84,7 +84,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 		assert.Len(t, retrieved.Attachments, 1)
 
 		// 验证附件
-		att, err := msgService.GetAttachment(mailbox.ID, message.ID, "att-001")
+		att, err := msgService.GetAttachment(t.Context(), mailbox.ID, message.ID, "att-001")
 		require.NoError(t, err)
 		assert.Equal(t, "test.pdf", att.Filename)
 		assert.Equal(t, "application/pdf", att.ContentType)
@@ -103,7 +103,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 			Token:     "list-token",
 			CreatedAt: time.Now(),
 		}
-		err := memStore.SaveMailbox(mailbox)
+		err := memStore.SaveMailbox(t.Context(), mailbox)
 		require.NoError(t, err)
 
 		// 创建多个邮件
@@ -118,7 +118,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 				IsRead:    false,
 			}
 
-			message, err := msgService.Create(input)
+			message, err := msgService.Create(t.Context(), input)
 			require.NoError(t, err)
 
 			// 保存到文件系统
@@ -130,7 +130,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 		}
 
 		// 列出邮件
-		messages, err := msgService.List(mailboxID)
+		messages, err := msgService.List(t.Context(), mailboxID)
 		require.NoError(t, err)
 		assert.Len(t, messages, 3)
 
@@ -154,7 +154,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 			Token:     "read-token",
 			CreatedAt: time.Now(),
 		}
-		err := memStore.SaveMailbox(mailbox)
+		err := memStore.SaveMailbox(t.Context(), mailbox)
 		require.NoError(t, err)
 
 		// 创建未读邮件
@@ -167,16 +167,16 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 			IsRead:    false,
 		}
 
-		message, err := msgService.Create(input)
+		message, err := msgService.Create(t.Context(), input)
 		require.NoError(t, err)
 		assert.False(t, message.IsRead)
 
 		// 标记为已读
-		err = msgService.MarkRead(mailboxID, message.ID)
+		err = msgService.MarkRead(t.Context(), mailboxID, message.ID)
 		require.NoError(t, err)
 
 		// 验证已读状态
-		retrieved, err := msgService.Get(mailboxID, message.ID)
+		retrieved, err := msgService.Get(t.Context(), mailboxID, message.ID)
 		require.NoError(t, err)
 		assert.True(t, retrieved.IsRead)
 	})
@@ -193,7 +193,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 			Token:     "delete-token",
 			CreatedAt: time.Now(),
 		}
-		err := memStore.SaveMailbox(mailbox)
+		err := memStore.SaveMailbox(t.Context(), mailbox)
 		require.NoError(t, err)
 
 		// 创建邮件
@@ -206,7 +206,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 			Raw:       "Raw content",
 		}
 
-		message, err := msgService.Create(input)
+		message, err := msgService.Create(t.Context(), input)
 		require.NoError(t, err)
 
 		// 验证文件存在
@@ -215,7 +215,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 		assert.NoError(t, err)
 
 		// 删除邮件（从数据库）
-		err = msgService.Delete(mailboxID, message.ID)
+		err = msgService.Delete(t.Context(), mailboxID, message.ID)
 		require.NoError(t, err)
 
 		// 手动从文件系统删除（在实际应用中，应该有后台任务清理）
@@ -227,7 +227,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 		assert.True(t, os.IsNotExist(err))
 
 		// 验证数据库中也已删除
-		_, err = msgService.Get(mailboxID, message.ID)
+		_, err = msgService.Get(t.Context(), mailboxID, message.ID)
 		assert.Error(t, err)
 	})
 
@@ -243,7 +243,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 			Token:     "clear-token",
 			CreatedAt: time.Now(),
 		}
-		err := memStore.SaveMailbox(mailbox)
+		err := memStore.SaveMailbox(t.Context(), mailbox)
 		require.NoError(t, err)
 
 		// 创建多个邮件
@@ -257,7 +257,7 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 				Text:      "Content " + string(rune('0'+i)),
 			}
 
-			message, err := msgService.Create(input)
+			message, err := msgService.Create(t.Context(), input)
 			require.NoError(t, err)
 			messageIDs = append(messageIDs, message.ID)
 
@@ -268,12 +268,12 @@ func TestIntegration_MessageServiceWithFilesystem(t *testing.T) {
 		}
 
 		// 清空邮箱
-		count, err := msgService.ClearAll(mailboxID)
+		count, err := msgService.ClearAll(t.Context(), mailboxID)
 		require.NoError(t, err)
 		assert.Equal(t, 5, count)
 
 		// 验证邮件已删除
-		messages, err := msgService.List(mailboxID)
+		messages, err := msgService.List(t.Context(), mailboxID)
 		require.NoError(t, err)
 		assert.Len(t, messages, 0)
 
@@ -309,7 +309,7 @@ func TestIntegration_LargeEmailHandling(t *testing.T) {
 			Token:     "large-token",
 			CreatedAt: time.Now(),
 		}
-		err := memStore.SaveMailbox(mailbox)
+		err := memStore.SaveMailbox(t.Context(), mailbox)
 		require.NoError(t, err)
 
 		// 创建大邮件（带多个大附件）
@@ -344,7 +344,7 @@ func TestIntegration_LargeEmailHandling(t *testing.T) {
 		}
 
 		// 创建邮件
-		message, err := msgService.Create(input)
+		message, err := msgService.Create(t.Context(), input)
 		require.NoError(t, err)
 
 		// 保存到文件系统
@@ -362,7 +362,7 @@ func TestIntegration_LargeEmailHandling(t *testing.T) {
 		}
 
 		// 读取邮件
-		retrieved, err := msgService.Get(mailboxID, message.ID)
+		retrieved, err := msgService.Get(t.Context(), mailboxID, message.ID)
 		require.NoError(t, err)
 		assert.Equal(t, len(largeText), len(retrieved.Text))
 		assert.Equal(t, len(largeHTML), len(retrieved.HTML))
@@ -407,7 +407,7 @@ func TestIntegration_ConcurrentMessageCreation(t *testing.T) {
 			Token:     "concurrent-token",
 			CreatedAt: time.Now(),
 		}
-		err := memStore.SaveMailbox(mailbox)
+		err := memStore.SaveMailbox(t.Context(), mailbox)
 		require.NoError(t, err)
 
 		// 并发创建邮件
@@ -426,7 +426,7 @@ func TestIntegration_ConcurrentMessageCreation(t *testing.T) {
 					Raw:       "Raw " + string(rune('0'+index)),
 				}
 
-				message, err := msgService.Create(input)
+				message, err := msgService.Create(t.Context(), input)
 				if err != nil {
 					errors <- err
 					done <- false
@@ -469,7 +469,7 @@ func TestIntegration_ConcurrentMessageCreation(t *testing.T) {
 		assert.Equal(t, numMessages, successCount)
 
 		// 验证所有邮件都已创建
-		messages, err := msgService.List(mailboxID)
+		messages, err := msgService.List(t.Context(), mailboxID)
 		require.NoError(t, err)
 		assert.Equal(t, numMessages, len(messages))
 	})
@@ -489,17 +489,17 @@ func TestIntegration_ErrorHandling(t *testing.T) {
 	msgService.SetFilesystemStore(fsStore)
 
 	t.Run("get non-existent message", func(t *testing.T) {
-		_, err := msgService.Get("non-existent-mailbox", "non-existent-message")
+		_, err := msgService.Get(t.Context(), "non-existent-mailbox", "non-existent-message")
 		assert.Error(t, err)
 	})
 
 	t.Run("get attachment from non-existent message", func(t *testing.T) {
-		_, err := msgService.GetAttachment("non-existent-mailbox", "non-existent-message", "att-001")
+		_, err := msgService.GetAttachment(t.Context(), "non-existent-mailbox", "non-existent-message", "att-001")
 		assert.Error(t, err)
 	})
 
 	t.Run("delete non-existent message", func(t *testing.T) {
-		err := msgService.Delete("non-existent-mailbox", "non-existent-message")
+		err := msgService.Delete(t.Context(), "non-existent-mailbox", "non-existent-message")
 		// 删除不存在的消息可能不报错（取决于实现）
 		// 主要是确保不会 panic
 		_ = err
@@ -517,7 +517,7 @@ func TestIntegration_ErrorHandling(t *testing.T) {
 			Token:     "error-token",
 			CreatedAt: time.Now(),
 		}
-		err := memStore.SaveMailbox(mailbox)
+		err := memStore.SaveMailbox(t.Context(), mailbox)
 		require.NoError(t, err)
 
 		// 创建邮件（即使文件系统失败，数据库操作应该成功）
@@ -529,12 +529,12 @@ func TestIntegration_ErrorHandling(t *testing.T) {
 			Text:      "Content",
 		}
 
-		message, err := msgService.Create(input)
+		message, err := msgService.Create(t.Context(), input)
 		require.NoError(t, err)
 		assert.NotEmpty(t, message.ID)
 
 		// 验证即使没有文件系统数据，元数据仍可获取
-		retrieved, err := msgService.Get(mailboxID, message.ID)
+		retrieved, err := msgService.Get(t.Context(), mailboxID, message.ID)
 		require.NoError(t, err)
 		assert.Equal(t, message.ID, retrieved.ID)
 		assert.Equal(t, input.Subject, retrieved.Subject)
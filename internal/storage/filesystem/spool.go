@@ -0,0 +1,244 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 流式入库的临时文件
+//
+// SMTP DATA 边读边写入 tmp/ 下的临时文件（spool），解析成功后按收件人硬链接到
+// 邮件目录的 raw.eml；大附件同样先流式写入临时文件并计算哈希，再提交为 blob，
+// 由一个 staging_ 引用占住，直到各收件人的邮件登记正式引用后释放。
+// 入库失败时删除临时文件、释放暂存引用，不会留下孤儿文件。
+
+const tmpDirName = "tmp"
+
+// stagingRefPrefix 暂存引用前缀（进程崩溃残留的暂存引用由 CleanupIngestTemp 清理）
+const stagingRefPrefix = "staging_"
+
+// Spool 原始邮件临时文件
+type Spool struct {
+	file *os.File
+	size int64
+}
+
+// Write 追加写入原始邮件内容
+func (s *Spool) Write(p []byte) (int, error) {
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+	return n, err
+}
+
+// Path 临时文件路径
+func (s *Spool) Path() string {
+	return s.file.Name()
+}
+
+// Size 已写入的字节数
+func (s *Spool) Size() int64 {
+	return s.size
+}
+
+// Close 关闭临时文件（保留文件，供链接到邮件目录）
+func (s *Spool) Close() error {
+	return s.file.Close()
+}
+
+// Discard 关闭并删除临时文件
+func (s *Spool) Discard() error {
+	s.file.Close()
+	if err := os.Remove(s.file.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// StagedBlob 已暂存的附件 blob
+type StagedBlob struct {
+	Hash string // 内容 SHA-256
+	Size int64  // 内容大小
+	ref  string // 暂存引用名
+}
+
+// tmpDir 获取临时目录（与 blob 同一文件系统，保证重命名是原子的）
+func (s *Store) tmpDir() (string, error) {
+	dir := filepath.Join(s.basePath, tmpDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	return dir, nil
+}
+
+// CreateSpool 创建原始邮件临时文件
+func (s *Store) CreateSpool() (*Spool, error) {
+	dir, err := s.tmpDir()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(dir, "ingest-*.eml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	return &Spool{file: file}, nil
+}
+
+// SaveMessageRawFile 将临时文件保存为邮件原始内容
+//
+// 优先硬链接，多个收件人共享同一份磁盘数据；不支持硬链接时回退为复制。
+func (s *Store) SaveMessageRawFile(mailboxID, messageID, srcPath string) (string, error) {
+	messagePath := s.getMessagePath(mailboxID, messageID)
+	if err := os.MkdirAll(messagePath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create message directory: %w", err)
+	}
+
+	rawFile := filepath.Join(messagePath, "raw.eml")
+	if err := os.Link(srcPath, rawFile); err != nil {
+		if err := copyFile(srcPath, rawFile); err != nil {
+			return "", fmt.Errorf("failed to write raw message: %w", err)
+		}
+	}
+
+	relPath, err := filepath.Rel(s.basePath, rawFile)
+	if err != nil {
+		return rawFile, nil
+	}
+	return relPath, nil
+}
+
+// StageBlob 将附件内容流式写入 blob 存储并登记暂存引用
+//
+// 调用方在各邮件登记正式引用后必须调用 ReleaseStagedBlob。
+func (s *Store) StageBlob(r io.Reader) (*StagedBlob, error) {
+	dir, err := s.tmpDir()
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(dir, "blob-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	staged := &StagedBlob{
+		Hash: hex.EncodeToString(hasher.Sum(nil)),
+		Size: size,
+		ref:  stagingRefPrefix + filepath.Base(tmpPath),
+	}
+	if err := s.commitBlobFile(staged.Hash, tmpPath, staged.ref); err != nil {
+		return nil, err
+	}
+	return staged, nil
+}
+
+// OpenStagedBlob 打开暂存的 blob 内容（入库前扫描附件），调用方负责关闭
+func (s *Store) OpenStagedBlob(staged *StagedBlob) (io.ReadCloser, error) {
+	file, err := os.Open(s.blobPath(staged.Hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open staged blob: %w", err)
+	}
+	return file, nil
+}
+
+// ReleaseStagedBlob 释放暂存引用（没有其他引用时删除 blob）
+func (s *Store) ReleaseStagedBlob(staged *StagedBlob) error {
+	if staged == nil {
+		return nil
+	}
+	return s.releaseBlob(staged.Hash, staged.ref)
+}
+
+// CheckWritable 在临时目录写入并删除一个探测文件，确认存储目录可写（就绪检查用）
+func (s *Store) CheckWritable() error {
+	dir, err := s.tmpDir()
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, "probe-*")
+	if err != nil {
+		return fmt.Errorf("storage directory not writable: %w", err)
+	}
+	_, err = file.Write([]byte("ok"))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(file.Name()); err == nil {
+		err = removeErr
+	}
+	if err != nil {
+		return fmt.Errorf("storage directory not writable: %w", err)
+	}
+	return nil
+}
+
+// CleanupIngestTemp 清理进程异常退出残留的临时文件和暂存引用，返回清理数量
+func (s *Store) CleanupIngestTemp(maxAge time.Duration) (int, error) {
+	cutoff := time.Now().Add(-maxAge)
+	count := 0
+
+	entries, err := os.ReadDir(filepath.Join(s.basePath, tmpDirName))
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if os.Remove(filepath.Join(s.basePath, tmpDirName, entry.Name())) == nil {
+			count++
+		}
+	}
+
+	refs, err := filepath.Glob(filepath.Join(s.basePath, blobsDirName, "*", "*", "*.refs", stagingRefPrefix+"*"))
+	if err != nil {
+		return count, err
+	}
+	for _, refFile := range refs {
+		info, err := os.Stat(refFile)
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		hash := strings.TrimSuffix(filepath.Base(filepath.Dir(refFile)), ".refs")
+		if s.releaseBlob(hash, filepath.Base(refFile)) == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// copyFile 复制文件内容
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
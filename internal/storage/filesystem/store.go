@@ -3,17 +3,23 @@ package filesystem
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"tempmail/backend/internal/domain"
 )
 
+// InlineAttachmentBytes 附件不超过该大小时直接读入内存，更大的附件流式读写
+const InlineAttachmentBytes = 256 << 10
+
 // Store 文件系统存储实现
 type Store struct {
 	basePath      string         // 邮件存储根目录
 	platformUtils *PlatformUtils // 平台兼容性工具
+	blobLocks     blobLocks      // 附件 blob 引用计数锁
 }
 
 // NewStore 创建文件系统存储实例
@@ -89,6 +95,25 @@ func (s *Store) SaveMessageMetadata(mailboxID, messageID string, message *domain
 	// 保存元数据: metadata.json
 	metaFile := filepath.Join(messagePath, "metadata.json")
 
+	data, err := MarshalMetadata(message)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(metaFile, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	relPath, err := filepath.Rel(s.basePath, metaFile)
+	if err != nil {
+		return metaFile, nil
+	}
+
+	return relPath, nil
+}
+
+// MarshalMetadata 生成邮件元数据 JSON（不含原始邮件和附件内容，对象存储使用同一格式）
+func MarshalMetadata(message *domain.Message) ([]byte, error) {
 	// 创建附件元数据列表（不包含 Content）
 	attachmentMetas := make([]struct {
 		ID          string `json:"id"`
@@ -97,6 +122,12 @@ func (s *Store) SaveMessageMetadata(mailboxID, messageID string, message *domain
 		ContentType string `json:"contentType"`
 		Size        int64  `json:"size"`
 		StoragePath string `json:"storagePath,omitempty"`
+		SHA256      string `json:"sha256,omitempty"`
+
+		DetectedContentType string `json:"detectedContentType,omitempty"`
+		ContentTypeMismatch bool   `json:"contentTypeMismatch,omitempty"`
+		Infected            bool   `json:"infected,omitempty"`
+		Threat              string `json:"threat,omitempty"`
 	}, len(message.Attachments))
 
 	for i, att := range message.Attachments {
@@ -107,6 +138,12 @@ func (s *Store) SaveMessageMetadata(mailboxID, messageID string, message *domain
 			ContentType string `json:"contentType"`
 			Size        int64  `json:"size"`
 			StoragePath string `json:"storagePath,omitempty"`
+			SHA256      string `json:"sha256,omitempty"`
+
+			DetectedContentType string `json:"detectedContentType,omitempty"`
+			ContentTypeMismatch bool   `json:"contentTypeMismatch,omitempty"`
+			Infected            bool   `json:"infected,omitempty"`
+			Threat              string `json:"threat,omitempty"`
 		}{
 			ID:          att.ID,
 			MessageID:   att.MessageID,
@@ -114,64 +151,74 @@ func (s *Store) SaveMessageMetadata(mailboxID, messageID string, message *domain
 			ContentType: att.ContentType,
 			Size:        att.Size,
 			StoragePath: att.StoragePath,
+			SHA256:      att.SHA256,
+
+			DetectedContentType: att.DetectedContentType,
+			ContentTypeMismatch: att.ContentTypeMismatch,
+			Infected:            att.Infected,
+			Threat:              att.Threat,
 		}
 	}
 
 	// 创建元数据结构（不包含 Raw，但包含 Attachments 元数据）
 	meta := struct {
-		ID          string    `json:"id"`
-		MailboxID   string    `json:"mailboxId"`
-		From        string    `json:"from"`
-		To          string    `json:"to"`
-		Subject     string    `json:"subject"`
-		Text        string    `json:"text"`
-		HTML        string    `json:"html"`
-		CreatedAt   time.Time `json:"createdAt"`
-		ReceivedAt  time.Time `json:"receivedAt"`
-		IsRead      bool      `json:"isRead"`
-		HasRaw      bool      `json:"hasRaw"`
-		HasHTML     bool      `json:"hasHtml"`
-		HasText     bool      `json:"hasText"`
-		Attachments []struct {
+		ID         string    `json:"id"`
+		MailboxID  string    `json:"mailboxId"`
+		From       string    `json:"from"`
+		To         string    `json:"to"`
+		Subject    string    `json:"subject"`
+		Text       string    `json:"text"`
+		HTML       string    `json:"html"`
+		CreatedAt  time.Time `json:"createdAt"`
+		ReceivedAt time.Time `json:"receivedAt"`
+		IsRead     bool      `json:"isRead"`
+		HasRaw     bool      `json:"hasRaw"`
+		HasHTML    bool      `json:"hasHtml"`
+		HasText    bool      `json:"hasText"`
+		// 纯文本由 HTML 转换生成
+		TextDerivedFromHTML bool `json:"textDerivedFromHtml,omitempty"`
+		// 检测语言与译文缓存
+		DetectedLanguage string            `json:"detectedLanguage,omitempty"`
+		TranslatedBodies map[string]string `json:"translatedBodies,omitempty"`
+		Attachments      []struct {
 			ID          string `json:"id"`
 			MessageID   string `json:"messageId"`
 			Filename    string `json:"filename"`
 			ContentType string `json:"contentType"`
 			Size        int64  `json:"size"`
 			StoragePath string `json:"storagePath,omitempty"`
+			SHA256      string `json:"sha256,omitempty"`
+
+			DetectedContentType string `json:"detectedContentType,omitempty"`
+			ContentTypeMismatch bool   `json:"contentTypeMismatch,omitempty"`
+			Infected            bool   `json:"infected,omitempty"`
+			Threat              string `json:"threat,omitempty"`
 		} `json:"attachments,omitempty"`
 	}{
-		ID:          message.ID,
-		MailboxID:   message.MailboxID,
-		From:        message.From,
-		To:          message.To,
-		Subject:     message.Subject,
-		Text:        message.Text,
-		HTML:        message.HTML,
-		CreatedAt:   message.CreatedAt,
-		ReceivedAt:  message.ReceivedAt,
-		IsRead:      message.IsRead,
-		HasRaw:      message.HasRaw,
-		HasHTML:     message.HasHTML,
-		HasText:     message.HasText,
-		Attachments: attachmentMetas,
+		ID:                  message.ID,
+		MailboxID:           message.MailboxID,
+		From:                message.From,
+		To:                  message.To,
+		Subject:             message.Subject,
+		Text:                message.Text,
+		HTML:                message.HTML,
+		CreatedAt:           message.CreatedAt,
+		ReceivedAt:          message.ReceivedAt,
+		IsRead:              message.IsRead,
+		HasRaw:              message.HasRaw,
+		HasHTML:             message.HasHTML,
+		HasText:             message.HasText,
+		DetectedLanguage:    message.DetectedLanguage,
+		TextDerivedFromHTML: message.TextDerivedFromHTML,
+		TranslatedBodies:    message.TranslatedBodies,
+		Attachments:         attachmentMetas,
 	}
 
 	data, err := json.MarshalIndent(meta, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal metadata: %w", err)
-	}
-
-	if err := os.WriteFile(metaFile, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to write metadata: %w", err)
-	}
-
-	relPath, err := filepath.Rel(s.basePath, metaFile)
-	if err != nil {
-		return metaFile, nil
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
 	}
-
-	return relPath, nil
+	return data, nil
 }
 
 // GetMessageMetadata 读取邮件元数据
@@ -197,6 +244,10 @@ func (s *Store) GetMessageMetadata(mailboxID, messageID string) (*domain.Message
 // ========== 附件存储 ==========
 
 // SaveAttachment 保存邮件附件
+//
+// 附件内容按 SHA-256 存入共享 blob，邮件目录下只保留旁路元数据，
+// 返回 blob 的相对路径并回填 attachment.SHA256。
+// Content 为空且已有 SHA256 时（流式暂存的大附件），直接引用已存在的 blob。
 func (s *Store) SaveAttachment(mailboxID, messageID, attachmentID string, attachment *domain.Attachment) (string, error) {
 	// 创建附件目录: /data/mails/{mailboxID}/{YYYY-MM-DD}/{messageID}/attachments/
 	attachPath := filepath.Join(s.getMessagePath(mailboxID, messageID), "attachments")
@@ -204,52 +255,105 @@ func (s *Store) SaveAttachment(mailboxID, messageID, attachmentID string, attach
 		return "", fmt.Errorf("failed to create attachment directory: %w", err)
 	}
 
-	// 生成安全的文件名（使用 SHA256 前 16 位 + 原始文件名）
-	safeFilename := s.generateSafeFilename(attachmentID, attachment.Filename)
-	attachFile := filepath.Join(attachPath, safeFilename)
-
-	// 保存附件内容
-	if err := os.WriteFile(attachFile, attachment.Content, 0644); err != nil {
-		return "", fmt.Errorf("failed to write attachment: %w", err)
+	hash := attachment.SHA256
+	if attachment.Content == nil && hash != "" {
+		if err := s.addBlobRef(hash, blobRef(messageID, attachmentID)); err != nil {
+			return "", err
+		}
+	} else {
+		hash = hashContent(attachment.Content)
+		if _, err := s.putBlob(hash, attachment.Content, blobRef(messageID, attachmentID)); err != nil {
+			return "", err
+		}
+		attachment.SHA256 = hash
 	}
 
-	// 保存附件元数据
+	// 保存附件元数据（文件名沿用旧格式，便于兼容迁移前的数据）
+	safeFilename := s.generateSafeFilename(attachmentID, attachment.Filename)
 	metaFile := filepath.Join(attachPath, safeFilename+".meta.json")
-	meta := struct {
-		ID          string `json:"id"`
-		Filename    string `json:"filename"`
-		ContentType string `json:"contentType"`
-		Size        int64  `json:"size"`
-		SavedAt     string `json:"savedAt"`
-	}{
+	meta := &attachmentMeta{
 		ID:          attachmentID,
 		Filename:    attachment.Filename,
 		ContentType: attachment.ContentType,
 		Size:        attachment.Size,
+		SHA256:      hash,
 		SavedAt:     time.Now().Format(time.RFC3339),
 	}
-
-	metaData, _ := json.MarshalIndent(meta, "", "  ")
-	if err := os.WriteFile(metaFile, metaData, 0644); err != nil {
+	if err := writeAttachmentMeta(metaFile, meta); err != nil {
+		s.releaseBlob(hash, blobRef(messageID, attachmentID))
 		return "", fmt.Errorf("failed to write attachment metadata: %w", err)
 	}
 
 	// 返回相对存储路径（便于持久化到数据库）
-	relPath, err := filepath.Rel(s.basePath, attachFile)
+	blobFile := s.blobPath(hash)
+	relPath, err := filepath.Rel(s.basePath, blobFile)
 	if err != nil {
 		// 如果计算相对路径失败，仍返回绝对路径
-		return attachFile, nil
+		return blobFile, nil
 	}
 
 	return relPath, nil
 }
 
-// GetAttachment 读取邮件附件
+// GetAttachment 获取邮件附件
+//
+// 小附件直接填充 Content；大附件不读入内存，通过 Open 懒加载文件内容。
 func (s *Store) GetAttachment(mailboxID, messageID, attachmentID string) (*domain.Attachment, error) {
+	attachmentMeta, attachFile, err := s.resolveAttachment(mailboxID, messageID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(attachFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("attachment file not found")
+		}
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	if info.Size() <= InlineAttachmentBytes {
+		content, err := os.ReadFile(attachFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment: %w", err)
+		}
+		attachmentMeta.Content = content
+	}
+	attachmentMeta.SetOpener(func() (io.ReadCloser, error) {
+		file, err := os.Open(attachFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open attachment: %w", err)
+		}
+		return file, nil
+	})
+	return attachmentMeta, nil
+}
+
+// OpenAttachment 以流的方式打开邮件附件，调用方负责关闭
+func (s *Store) OpenAttachment(mailboxID, messageID, attachmentID string) (io.ReadCloser, *domain.Attachment, error) {
+	attachmentMeta, attachFile, err := s.resolveAttachment(mailboxID, messageID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(attachFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("attachment file not found")
+		}
+		return nil, nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	return file, attachmentMeta, nil
+}
+
+// resolveAttachment 解析附件元数据和实际内容文件路径
+//
+// 优先按旁路元数据中的哈希定位 blob，没有哈希时回退到迁移前的私有副本。
+func (s *Store) resolveAttachment(mailboxID, messageID, attachmentID string) (*domain.Attachment, string, error) {
 	// 先读取邮件元数据以获取附件信息
 	metadata, err := s.GetMessageMetadata(mailboxID, messageID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get message metadata: %w", err)
+		return nil, "", fmt.Errorf("failed to get message metadata: %w", err)
 	}
 
 	// 查找指定附件的元数据
@@ -261,40 +365,65 @@ func (s *Store) GetAttachment(mailboxID, messageID, attachmentID string) (*domai
 		}
 	}
 	if attachmentMeta == nil {
-		return nil, fmt.Errorf("attachment not found in metadata")
+		return nil, "", fmt.Errorf("attachment not found in metadata")
 	}
 
-	// 读取附件文件内容
 	safeFilename := s.generateSafeFilename(attachmentID, attachmentMeta.Filename)
-	attachFile := filepath.Join(s.getMessagePath(mailboxID, messageID), "attachments", safeFilename)
+	legacyFile := filepath.Join(s.getMessagePath(mailboxID, messageID), "attachments", safeFilename)
 
-	content, err := os.ReadFile(attachFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("attachment file not found")
+	hash := attachmentMeta.SHA256
+	if hash == "" {
+		if sidecar, err := readAttachmentMeta(legacyFile + ".meta.json"); err == nil {
+			hash = sidecar.SHA256
 		}
-		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if len(hash) >= 4 {
+		attachmentMeta.SHA256 = hash
+		return attachmentMeta, s.blobPath(hash), nil
 	}
 
-	// 填充内容并返回完整的附件对象
-	attachmentMeta.Content = content
-	return attachmentMeta, nil
+	return attachmentMeta, legacyFile, nil
 }
 
 // ========== 清理操作 ==========
 
-// DeleteMessage 删除邮件及其所有文件
+// DeleteMessage 删除邮件及其所有文件（同时释放附件 blob 引用）
 func (s *Store) DeleteMessage(mailboxID, messageID string) error {
 	messagePath := s.getMessagePath(mailboxID, messageID)
-	return os.RemoveAll(messagePath)
+	return s.removeMessageDir(messagePath)
 }
 
-// DeleteMailbox 删除邮箱的所有邮件
+// DeleteMailbox 删除邮箱的所有邮件（同时释放附件 blob 引用）
 func (s *Store) DeleteMailbox(mailboxID string) error {
 	mailboxPath := filepath.Join(s.basePath, "mails", mailboxID)
+	messagePaths, _ := filepath.Glob(filepath.Join(mailboxPath, "*", "*"))
+	for _, messagePath := range messagePaths {
+		if err := s.releaseMessageBlobs(messagePath); err != nil {
+			return err
+		}
+	}
 	return os.RemoveAll(mailboxPath)
 }
 
+// ListMailboxIDs 列出文件系统中存有内容的邮箱 ID（供对账任务查找孤儿目录）
+func (s *Store) ListMailboxIDs() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.basePath, "mails"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
+
 // CleanupExpired 清理过期的邮件（基于目录的修改时间）
 func (s *Store) CleanupExpired(retentionDays int) (int, error) {
 	count := 0
@@ -348,7 +477,7 @@ func (s *Store) CleanupExpired(retentionDays int) (int, error) {
 				}
 
 				if info.ModTime().Before(cutoffTime) {
-					if err := os.RemoveAll(messagePath); err == nil {
+					if err := s.removeMessageDir(messagePath); err == nil {
 						count++
 					}
 				}
@@ -373,9 +502,17 @@ func (s *Store) CleanupExpired(retentionDays int) (int, error) {
 
 // getMessagePath 获取邮件存储路径
 // 格式: /data/mails/{mailboxID}/{YYYY-MM-DD}/{messageID}/
+// 邮件已存在于其他日期目录时返回该目录，否则返回当天目录
 func (s *Store) getMessagePath(mailboxID, messageID string) string {
 	today := time.Now().Format("2006-01-02")
-	return filepath.Join(s.basePath, "mails", mailboxID, today, messageID)
+	path := filepath.Join(s.basePath, "mails", mailboxID, today, messageID)
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	if matches, _ := filepath.Glob(filepath.Join(s.basePath, "mails", mailboxID, "*", messageID)); len(matches) > 0 {
+		return matches[0]
+	}
+	return path
 }
 
 // generateSafeFilename 生成安全的文件名
@@ -394,10 +531,13 @@ func (s *Store) generateSafeFilename(attachmentID, originalFilename string) stri
 }
 
 // GetStorageStats 获取存储统计信息
+//
+// logical_size_bytes 按引用展开计算（每个附件引用都算一份），
+// physical_size_bytes 为磁盘实际占用，两者之差即去重节省的空间。
 func (s *Store) GetStorageStats() (map[string]interface{}, error) {
 	mailsPath := filepath.Join(s.basePath, "mails")
 
-	var totalSize int64
+	var mailsSize int64
 	var messageCount int
 	var attachmentCount int
 
@@ -407,14 +547,15 @@ func (s *Store) GetStorageStats() (map[string]interface{}, error) {
 		}
 
 		if !info.IsDir() {
-			totalSize += info.Size()
+			mailsSize += info.Size()
 
 			if filepath.Ext(path) == ".eml" {
 				messageCount++
 			}
 
+			// 每个附件都有一个旁路元数据文件
 			if filepath.Base(filepath.Dir(path)) == "attachments" &&
-				filepath.Ext(path) != ".json" {
+				strings.HasSuffix(path, ".meta.json") {
 				attachmentCount++
 			}
 		}
@@ -426,11 +567,68 @@ func (s *Store) GetStorageStats() (map[string]interface{}, error) {
 		return nil, err
 	}
 
+	var blobSize, blobLogicalSize int64
+	var blobCount int
+	filepath.Walk(filepath.Join(s.basePath, blobsDirName), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if strings.HasSuffix(path, ".refs") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		blobCount++
+		blobSize += info.Size()
+		blobLogicalSize += info.Size() * int64(s.blobRefCount(filepath.Base(path)))
+		return nil
+	})
+
+	physical := mailsSize + blobSize
+	logical := mailsSize + blobLogicalSize
+
 	return map[string]interface{}{
-		"total_size_bytes": totalSize,
-		"total_size_mb":    float64(totalSize) / 1024 / 1024,
-		"message_count":    messageCount,
-		"attachment_count": attachmentCount,
-		"base_path":        s.basePath,
+		"total_size_bytes":    physical,
+		"total_size_mb":       float64(physical) / 1024 / 1024,
+		"physical_size_bytes": physical,
+		"logical_size_bytes":  logical,
+		"dedup_saved_bytes":   logical - physical,
+		"message_count":       messageCount,
+		"attachment_count":    attachmentCount,
+		"blob_count":          blobCount,
+		"base_path":           s.basePath,
 	}, nil
 }
+
+// ========== 状态历史 ==========
+
+// SaveUptimeHistory 保存运行时间历史（status/uptime.json）
+func (s *Store) SaveUptimeHistory(data []byte) error {
+	statusPath := filepath.Join(s.basePath, "status")
+	if err := os.MkdirAll(statusPath, 0755); err != nil {
+		return fmt.Errorf("failed to create status directory: %w", err)
+	}
+
+	file := filepath.Join(statusPath, "uptime.json")
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write uptime history: %w", err)
+	}
+	return os.Rename(tmp, file)
+}
+
+// LoadUptimeHistory 读取运行时间历史，不存在时返回空
+func (s *Store) LoadUptimeHistory() ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.basePath, "status", "uptime.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read uptime history: %w", err)
+	}
+	return data, nil
+}
@@ -267,22 +267,18 @@ func TestSaveAttachment(t *testing.T) {
 	}
 
 	t.Run("save attachment successfully", func(t *testing.T) {
-		_, err := store.SaveAttachment(mailboxID, messageID, attachmentID, attachment)
+		storagePath, err := store.SaveAttachment(mailboxID, messageID, attachmentID, attachment)
 		require.NoError(t, err)
 
-		// 验证附件文件已创建
-		safeFilename := store.generateSafeFilename(attachmentID, attachment.Filename)
-		attachPath := filepath.Join(store.getMessagePath(mailboxID, messageID), "attachments", safeFilename)
-		_, err = os.Stat(attachPath)
-		assert.NoError(t, err)
-
-		// 验证附件内容
-		content, err := os.ReadFile(attachPath)
+		// 验证附件内容写入了内容寻址 blob
+		content, err := os.ReadFile(filepath.Join(store.basePath, storagePath))
 		require.NoError(t, err)
 		assert.Equal(t, attachment.Content, content)
+		assert.Equal(t, hashContent(attachment.Content), attachment.SHA256)
 
 		// 验证元数据文件已创建
-		metaFile := attachPath + ".meta.json"
+		safeFilename := store.generateSafeFilename(attachmentID, attachment.Filename)
+		metaFile := filepath.Join(store.getMessagePath(mailboxID, messageID), "attachments", safeFilename+".meta.json")
 		_, err = os.Stat(metaFile)
 		assert.NoError(t, err)
 	})
@@ -714,13 +710,11 @@ func TestEdgeCases(t *testing.T) {
 			Content:     []byte{},
 		}
 
-		_, err := store.SaveAttachment(mailboxID, messageID, attachmentID, attachment)
+		storagePath, err := store.SaveAttachment(mailboxID, messageID, attachmentID, attachment)
 		require.NoError(t, err)
 
 		// 验证文件已创建
-		safeFilename := store.generateSafeFilename(attachmentID, "empty.txt")
-		attachPath := filepath.Join(store.getMessagePath(mailboxID, messageID), "attachments", safeFilename)
-		content, err := os.ReadFile(attachPath)
+		content, err := os.ReadFile(filepath.Join(store.basePath, storagePath))
 		require.NoError(t, err)
 		assert.Empty(t, content)
 	})
@@ -0,0 +1,23 @@
+package hybrid
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Abuse Report Repository ==========
+//
+// 滥用举报直接读写 PostgreSQL，不进入缓存。
+
+func (s *Store) SaveAbuseReport(ctx context.Context, report *domain.AbuseReport) error {
+	return s.postgres.SaveAbuseReport(ctx, report)
+}
+
+func (s *Store) GetAbuseReport(ctx context.Context, id string) (*domain.AbuseReport, error) {
+	return s.postgres.GetAbuseReport(ctx, id)
+}
+
+func (s *Store) ListAbuseReports(ctx context.Context, filter domain.AbuseReportFilter) ([]*domain.AbuseReport, error) {
+	return s.postgres.ListAbuseReports(ctx, filter)
+}
@@ -0,0 +1,24 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Analytics Repository ==========
+//
+// 统计计数由收集器在内存中合并后定期写入，直接读写 PostgreSQL，不进入缓存。
+
+func (s *Store) MergeAnalyticsBuckets(ctx context.Context, buckets []domain.AnalyticsBucket) error {
+	return s.postgres.MergeAnalyticsBuckets(ctx, buckets)
+}
+
+func (s *Store) ListAnalyticsBuckets(ctx context.Context, metric string, since, until time.Time) ([]domain.AnalyticsBucket, error) {
+	return s.postgres.ListAnalyticsBuckets(ctx, metric, since, until)
+}
+
+func (s *Store) DeleteAnalyticsBucketsBefore(ctx context.Context, before time.Time) (int64, error) {
+	return s.postgres.DeleteAnalyticsBucketsBefore(ctx, before)
+}
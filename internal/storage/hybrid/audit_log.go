@@ -0,0 +1,19 @@
+package hybrid
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Audit Log Repository ==========
+//
+// 审计记录只追加、按需查询，直接访问 PostgreSQL，不进入缓存。
+
+func (s *Store) CreateAuditLog(ctx context.Context, log *domain.AuditLog) error {
+	return s.postgres.CreateAuditLog(ctx, log)
+}
+
+func (s *Store) ListAuditLogs(ctx context.Context, filter domain.AuditLogFilter) ([]*domain.AuditLog, int64, error) {
+	return s.postgres.ListAuditLogs(ctx, filter)
+}
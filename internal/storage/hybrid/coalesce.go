@@ -0,0 +1,92 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/storage/cachekey"
+)
+
+// negativeCacheTTL 负缓存有效期（不存在的结果只短暂缓存，创建时立即清除）
+const negativeCacheTTL = 30 * time.Second
+
+// coalesceTimeout 合并查询的超时（与数据库单条记录读写的默认超时一致）
+const coalesceTimeout = 5 * time.Second
+
+// 缓存读取、合并查询与负缓存使用的操作名（同时作为指标标签）
+const (
+	opGetMailbox              = "get_mailbox"
+	opGetMailboxByAddress     = "get_mailbox_by_address"
+	opListMailboxSummaries    = "list_mailbox_summaries"
+	opGetSystemDomain         = "get_system_domain"
+	opGetSystemDomainByDomain = "get_system_domain_by_domain"
+	opGetDefaultSystemDomain  = "get_default_system_domain"
+	opListSystemDomains       = "list_system_domains"
+	opListActiveSystemDomains = "list_active_system_domains"
+	opGetMessage              = "get_message"
+	opListMessages            = "list_messages"
+	opGetMessageStats         = "get_message_stats"
+	opGetAPIKey               = "get_api_key"
+	opGetUserByAPIKey         = "get_user_by_api_key"
+	opGetSystemStatistics     = "get_system_statistics"
+)
+
+// CacheMetrics 缓存指标记录器（由 monitoring.Metrics 实现）
+type CacheMetrics interface {
+	RecordCacheCoalesced(operation string)
+	RecordCacheNegativeHit(operation string)
+	RecordCacheLookup(operation string, hit bool)
+}
+
+// SetCacheMetrics 设置缓存指标记录器
+func (s *Store) SetCacheMetrics(metrics CacheMetrics) {
+	s.metrics = metrics
+}
+
+// coalesce 合并同一键上的并发回源查询，只有一个请求真正访问数据库
+//
+// 查询在脱离调用方取消信号的 context 中执行（保留链路追踪等值，超时为 coalesceTimeout），
+// 发起查询的请求断开不会让等待同一结果的其他请求失败；每个调用方在自己的 ctx 取消时立即返回。
+// 返回值 leader 表示当前调用是否实际执行了查询；非 leader 拿到的是共享结果，
+// 调用方需复制后再返回，避免多个请求修改同一个对象。
+func (s *Store) coalesce(ctx context.Context, op, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, bool, error) {
+	// 只在收到结果后读取（channel 保证先于读取写入），提前返回的调用方不再访问
+	leader := false
+	ch := s.group.DoChan(op+":"+key, func() (interface{}, error) {
+		leader = true
+		queryCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), coalesceTimeout)
+		defer cancel()
+		return fn(queryCtx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	case res := <-ch:
+		if res.Shared && !leader && s.metrics != nil {
+			s.metrics.RecordCacheCoalesced(op)
+		}
+		return res.Val, leader, res.Err
+	}
+}
+
+// cacheHit 记录一次正向缓存读取的结果（err 为空即命中），用于统计命中率
+func (s *Store) cacheHit(op string, err error) bool {
+	hit := err == nil
+	if s.metrics != nil {
+		s.metrics.RecordCacheLookup(op, hit)
+	}
+	return hit
+}
+
+// isNegativeCached 检查负缓存（Redis 不可用时视为未命中，回源查询）
+func (s *Store) isNegativeCached(op string, key cachekey.Key) bool {
+	hit, err := s.redis.IsNotFound(key)
+	if err != nil || !hit {
+		return false
+	}
+	if s.metrics != nil {
+		s.metrics.RecordCacheNegativeHit(op)
+	}
+	return true
+}
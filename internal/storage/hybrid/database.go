@@ -0,0 +1,30 @@
+package hybrid
+
+import (
+	"tempmail/backend/internal/storage/mongo"
+	"tempmail/backend/internal/storage/postgres"
+)
+
+// sqlDatabase 把 *postgres.Store（mysql/postgres/sqlite）适配为 database
+type sqlDatabase struct {
+	*postgres.Store
+}
+
+// WithTransaction 在 SQL 事务中执行 fn，事务句柄同样以 database 形式传入
+func (d sqlDatabase) WithTransaction(fn func(tx database) error) error {
+	return d.Store.WithTransaction(func(tx *postgres.Store) error {
+		return fn(sqlDatabase{tx})
+	})
+}
+
+// mongoDatabase 把 *mongo.Store 适配为 database
+type mongoDatabase struct {
+	*mongo.Store
+}
+
+// WithTransaction 在 MongoDB 会话事务中执行 fn（单机部署时直接执行）
+func (d mongoDatabase) WithTransaction(fn func(tx database) error) error {
+	return d.Store.WithTransaction(func(tx *mongo.Store) error {
+		return fn(mongoDatabase{tx})
+	})
+}
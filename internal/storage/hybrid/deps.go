@@ -0,0 +1,251 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/cachekey"
+	"tempmail/backend/internal/storage/postgres"
+)
+
+// database 持久化层（*postgres.Store、*mongo.Store 经适配后实现，测试中可替换为桩）
+type database interface {
+	AddDomainWhitelistEntry(ctx context.Context, entry *domain.DomainWhitelistEntry) error
+	AddMessageTag(ctx context.Context, messageID, tagID string) error
+	CancelPendingDeliveries(ctx context.Context, mailboxID string) (int, error)
+	ClaimOutboxEvents(ctx context.Context, now, until time.Time, limit int) ([]*domain.OutboxEvent, error)
+	ClearSearchIndex(ctx context.Context) error
+	Close() error
+	CountMailboxes(ctx context.Context) (int64, error)
+	CountMessages(ctx context.Context) (int64, error)
+	CountSentMessages(ctx context.Context, filter domain.SentMessageFilter) (domain.SentMessageUsage, error)
+	CreateAuditLog(ctx context.Context, log *domain.AuditLog) error
+	CreateMailbox(ctx context.Context, mailbox *domain.Mailbox) error
+	CreateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error
+	CreateOAuthIdentity(ctx context.Context, identity *domain.OAuthIdentity) error
+	CreateOrganization(ctx context.Context, org *domain.Organization) error
+	CreateReservedPrefix(ctx context.Context, prefix *domain.ReservedPrefix) error
+	CreateTag(ctx context.Context, tag *domain.Tag) error
+	CreateUser(ctx context.Context, user *domain.User) error
+	CreateUserSession(ctx context.Context, session *domain.UserSession) error
+	CreateWebhook(ctx context.Context, webhook *domain.Webhook) error
+	DecrementMailboxCount(ctx context.Context, domainName string) error
+	DecrementSystemDomainMailboxCount(ctx context.Context, domainName string) error
+	DeleteAPIKey(ctx context.Context, id string) error
+	DeleteAlias(ctx context.Context, aliasID string) error
+	DeleteAnalyticsBucketsBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteExpiredUserSessions(ctx context.Context, now time.Time) (int64, error)
+	DeleteMailFlowCountersBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteAllMessages(ctx context.Context, mailboxID string) (int, error)
+	DeleteDistributionList(ctx context.Context, id string) error
+	DeleteDomainWhitelistEntry(ctx context.Context, domainID, id string) error
+	DeleteExpiredMailboxes(ctx context.Context) (int, error)
+	DeleteExpiredMessages(ctx context.Context, now time.Time) ([]domain.ExpiredMessages, error)
+	DeleteMailbox(ctx context.Context, id string) error
+	DeleteMailboxesByUserID(ctx context.Context, userID string) error
+	DeleteMailboxForward(ctx context.Context, mailboxID, id string) error
+	DeleteMessage(ctx context.Context, mailboxID, messageID string) error
+	DeleteMessageTags(ctx context.Context, messageID string) error
+	DeleteOrgInvite(ctx context.Context, token string) error
+	DeleteOrgMember(ctx context.Context, orgID, userID string) error
+	DeleteProcessedOutboxEvents(ctx context.Context, before time.Time) (int64, error)
+	DeleteReservedPrefix(ctx context.Context, id string) error
+	DeleteSystemDomain(ctx context.Context, domainID string) error
+	DeleteTag(ctx context.Context, id string) error
+	DeleteUnverifiedSystemDomains(ctx context.Context, before time.Time) (int, error)
+	DeleteUser(ctx context.Context, userID string) error
+	DeleteUserDomain(ctx context.Context, domainID string) error
+	DeleteUserSession(ctx context.Context, id string) error
+	DeleteWebhook(ctx context.Context, id string) error
+	ExtendMailbox(ctx context.Context, mailboxID string, expiresAt time.Time) error
+	FindReservedPrefixes(ctx context.Context, domainName, localPart string) ([]*domain.ReservedPrefix, error)
+	GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error)
+	GetAbuseReport(ctx context.Context, id string) (*domain.AbuseReport, error)
+	GetAPIKeyByKey(ctx context.Context, key string) (*domain.APIKey, error)
+	GetAlias(ctx context.Context, aliasID string) (*domain.MailboxAlias, error)
+	GetAliasByAddress(ctx context.Context, address string) (*domain.MailboxAlias, error)
+	GetAttachment(ctx context.Context, mailboxID, messageID, attachmentID string) (*domain.Attachment, error)
+	GetDefaultSystemDomain(ctx context.Context) (*domain.SystemDomain, error)
+	GetDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error)
+	GetDistributionList(ctx context.Context, id string) (*domain.DistributionList, error)
+	GetDistributionListByAddress(ctx context.Context, address string) (*domain.DistributionList, error)
+	GetDomainStatistics(ctx context.Context, domainName string) (mailboxCount, messageCount int, err error)
+	GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error)
+	GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error)
+	GetMailboxForward(ctx context.Context, mailboxID, id string) (*domain.MailboxForward, error)
+	GetMailboxesByAddresses(ctx context.Context, addresses []string) ([]domain.Mailbox, error)
+	GetMaintenanceJob(ctx context.Context, id string) (*domain.MaintenanceJob, error)
+	GetMessage(ctx context.Context, mailboxID, messageID string) (*domain.Message, error)
+	GetMessageRedaction(ctx context.Context, mailboxID, messageID string) (*domain.MessageRedaction, error)
+	GetMessageShare(ctx context.Context, id string) (*domain.MessageShare, error)
+	GetMessageStats(ctx context.Context, query domain.MessageStatsQuery) (*domain.MessageStats, error)
+	GetMessageTags(ctx context.Context, messageID string) ([]domain.Tag, error)
+	GetOAuthIdentity(ctx context.Context, provider, subject string) (*domain.OAuthIdentity, error)
+	GetOrgInvite(ctx context.Context, token string) (*domain.OrgInvite, error)
+	GetOrgMember(ctx context.Context, orgID, userID string) (*domain.OrgMember, error)
+	GetOrganization(ctx context.Context, id string) (*domain.Organization, error)
+	GetPendingDeliveries(ctx context.Context, limit int) ([]domain.WebhookDelivery, error)
+	GetSystemConfig(ctx context.Context) (*domain.SystemConfig, error)
+	GetSystemDomain(ctx context.Context, domainID string) (*domain.SystemDomain, error)
+	GetSystemDomainByDomain(ctx context.Context, domainName string) (*domain.SystemDomain, error)
+	GetSystemStatistics(ctx context.Context) (*domain.SystemStatistics, error)
+	GetTag(ctx context.Context, id string) (*domain.Tag, error)
+	GetTagByName(ctx context.Context, userID, name string) (*domain.Tag, error)
+	GetUserByAPIKey(ctx context.Context, apiKey string) (*domain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*domain.User, error)
+	GetUserDomain(ctx context.Context, domainID string) (*domain.UserDomain, error)
+	GetUserDomainByDomain(ctx context.Context, domainName string) (*domain.UserDomain, error)
+	GetUserSession(ctx context.Context, id string) (*domain.UserSession, error)
+	GetWebhook(ctx context.Context, id string) (*domain.Webhook, error)
+	IncrementMailboxCount(ctx context.Context, domainName string) error
+	IndexMessages(ctx context.Context, docs []*domain.SearchDocument) error
+	IncrementSystemDomainMailboxCount(ctx context.Context, domainName string) error
+	IsLocalPartWhitelisted(ctx context.Context, domainID, localPart string) (bool, error)
+	ListAPIKeysByUserID(ctx context.Context, userID string) ([]*domain.APIKey, error)
+	ListAbuseReports(ctx context.Context, filter domain.AbuseReportFilter) ([]*domain.AbuseReport, error)
+	ListActiveSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error)
+	ListAnalyticsBuckets(ctx context.Context, metric string, since, until time.Time) ([]domain.AnalyticsBucket, error)
+	ListAuditLogs(ctx context.Context, filter domain.AuditLogFilter) ([]*domain.AuditLog, int64, error)
+	ListMailFlowCounters(ctx context.Context, since, until time.Time) ([]domain.MailFlowCounter, error)
+	ListAliasesByMailboxID(ctx context.Context, mailboxID string) ([]*domain.MailboxAlias, error)
+	ListAllUserDomains(ctx context.Context) ([]*domain.UserDomain, error)
+	ListDeadLetters(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error)
+	ListDistributionListDeliveries(ctx context.Context, listID string, limit int) ([]*domain.DistributionListDelivery, error)
+	ListDistributionListsByOrgID(ctx context.Context, orgID string) ([]*domain.DistributionList, error)
+	ListDistributionListsByUserID(ctx context.Context, userID string) ([]*domain.DistributionList, error)
+	ListDomainWhitelist(ctx context.Context, domainID string) ([]*domain.DomainWhitelistEntry, error)
+	ListExpiredMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error)
+	ListIdleMailboxes(ctx context.Context, before, now time.Time) ([]domain.Mailbox, error)
+	ListMailboxForwards(ctx context.Context, mailboxID string) ([]*domain.MailboxForward, error)
+	ListMailboxIDsAfter(ctx context.Context, afterID string, limit int) ([]string, error)
+	ListMailboxSummariesByOrgID(ctx context.Context, orgID string) ([]domain.MailboxSummary, error)
+	ListMailboxSummariesByUserID(ctx context.Context, userID string) ([]domain.MailboxSummary, error)
+	ListMailboxes(ctx context.Context) []domain.Mailbox
+	ListMailboxesByOrgID(ctx context.Context, orgID string) []domain.Mailbox
+	ListMailboxesByUserID(ctx context.Context, userID string) []domain.Mailbox
+	ListMaintenanceJobs(ctx context.Context) ([]*domain.MaintenanceJob, error)
+	ListMessages(ctx context.Context, mailboxID string) ([]domain.Message, error)
+	ListMessageShares(ctx context.Context, mailboxID string) ([]*domain.MessageShare, error)
+	ListMessagesAfter(ctx context.Context, afterID string, limit int) ([]domain.Message, error)
+	ListMessagesByTag(ctx context.Context, tagID string) ([]domain.Message, error)
+	ListMessagesPage(ctx context.Context, query domain.MessageListQuery) (*domain.MessagePage, error)
+	ListOAuthIdentities(ctx context.Context, userID string) ([]*domain.OAuthIdentity, error)
+	ListOrgMembers(ctx context.Context, orgID string) ([]*domain.OrgMember, error)
+	ListPendingForwardDeliveries(ctx context.Context, now time.Time, limit int) ([]*domain.ForwardDelivery, error)
+	ListPublicMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error)
+	ListOrgMembershipsByUserID(ctx context.Context, userID string) ([]*domain.OrgMember, error)
+	ListReservedPrefixes(ctx context.Context, userID *string) ([]*domain.ReservedPrefix, error)
+	ListSentMessages(ctx context.Context, mailboxID string) ([]*domain.SentMessage, error)
+	ListSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error)
+	ListTags(ctx context.Context, userID string) ([]domain.TagWithCount, error)
+	ListTagsByOrgID(ctx context.Context, orgID string) ([]domain.TagWithCount, error)
+	ListUserDomainsByOrgID(ctx context.Context, orgID string) ([]*domain.UserDomain, error)
+	ListUserDomainsByUserID(ctx context.Context, userID string) ([]*domain.UserDomain, error)
+	ListUserSessions(ctx context.Context, userID string) ([]*domain.UserSession, error)
+	ListUsers(ctx context.Context, page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error)
+	ListWebhooks(ctx context.Context, userID string) ([]domain.Webhook, error)
+	ListWebhooksByMailbox(ctx context.Context, mailboxID string) ([]domain.Webhook, error)
+	ListWebhooksByOrgID(ctx context.Context, orgID string) ([]domain.Webhook, error)
+	MarkMailboxIdle(ctx context.Context, mailboxID string, at time.Time, shortenTo *time.Time) error
+	MarkMessageRead(ctx context.Context, mailboxID, messageID string) error
+	MarkMessageUnread(ctx context.Context, mailboxID, messageID string) error
+	MergeAnalyticsBuckets(ctx context.Context, buckets []domain.AnalyticsBucket) error
+	MergeMailFlowCounters(ctx context.Context, counters []domain.MailFlowCounter) error
+	Ping(ctx context.Context) error
+	RecordDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	RecordMessageShareView(ctx context.Context, id string, at time.Time) error
+	RecountMailbox(ctx context.Context, mailboxID string) (bool, error)
+	RemoveMessageTag(ctx context.Context, messageID, tagID string) error
+	RotateUserSession(ctx context.Context, session *domain.UserSession, previousRefreshID string) error
+	SaveAPIKey(ctx context.Context, apiKey *domain.APIKey) error
+	SaveAbuseReport(ctx context.Context, report *domain.AbuseReport) error
+	SaveAlias(ctx context.Context, alias *domain.MailboxAlias) error
+	SaveDistributionList(ctx context.Context, list *domain.DistributionList) error
+	SaveDistributionListDelivery(ctx context.Context, delivery *domain.DistributionListDelivery) error
+	SaveForwardDelivery(ctx context.Context, delivery *domain.ForwardDelivery) error
+	SaveMailbox(ctx context.Context, mailbox *domain.Mailbox) error
+	SaveMailboxForward(ctx context.Context, forward *domain.MailboxForward) error
+	SaveMessage(ctx context.Context, message *domain.Message) error
+	SaveMessageRedaction(ctx context.Context, redaction *domain.MessageRedaction) error
+	SaveMessageShare(ctx context.Context, share *domain.MessageShare) error
+	SaveMessages(ctx context.Context, messages []*domain.Message) error
+	SaveMessagesWithOutbox(ctx context.Context, messages []*domain.Message, events []*domain.OutboxEvent) error
+	SaveOrgInvite(ctx context.Context, invite *domain.OrgInvite) error
+	SaveOrgMember(ctx context.Context, member *domain.OrgMember) error
+	SaveSentMessage(ctx context.Context, message *domain.SentMessage) error
+	SaveSystemConfig(ctx context.Context, config *domain.SystemConfig) error
+	SaveSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error
+	SaveUserDomain(ctx context.Context, userDomain *domain.UserDomain) error
+	SearchIndex(ctx context.Context, query domain.IndexQuery) (*domain.MessageSearchResult, error)
+	SearchMessages(ctx context.Context, criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error)
+	SetDefaultSystemDomain(ctx context.Context, domainID string) error
+	SetMessageDerivedText(ctx context.Context, mailboxID, messageID, text string) error
+	SetMessageExpiry(ctx context.Context, mailboxID, messageID string, expiresAt *time.Time) error
+	SetMessageQuarantined(ctx context.Context, mailboxID, messageID string, quarantined bool) error
+	SetSlowQueryLog(threshold time.Duration, sink postgres.SlowQuerySink)
+	TouchMailbox(ctx context.Context, mailboxID string, at time.Time) error
+	TouchOAuthIdentity(ctx context.Context, id string, at time.Time) error
+	UpdateAPIKeyLastUsed(ctx context.Context, id string) error
+	UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	UpdateLastLogin(ctx context.Context, userID string) error
+	UpdateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error
+	UpdateOutboxEvent(ctx context.Context, event *domain.OutboxEvent) error
+	UpdateSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error
+	UpdateTag(ctx context.Context, tag *domain.Tag) error
+	UpdateUser(ctx context.Context, user *domain.User) error
+	UpdateUserDomain(ctx context.Context, userDomain *domain.UserDomain) error
+	UpdateWebhook(ctx context.Context, webhook *domain.Webhook) error
+	WithTransaction(fn func(tx database) error) error
+}
+
+// cache 缓存层（*redis.Cache 实现，测试中可替换为桩）
+type cache interface {
+	AddToBlacklist(ctx context.Context, jti string, ttl time.Duration) error
+	CacheAPIKey(apiKey *domain.APIKey, ttl time.Duration) error
+	CacheAPIKeyUser(apiKey, userID string, ttl time.Duration) error
+	CacheConfig(config *domain.SystemConfig, ttl time.Duration) error
+	CacheDefaultSystemDomain(sysDomain *domain.SystemDomain, ttl time.Duration) error
+	CacheMailbox(mailbox *domain.Mailbox, ttl time.Duration) error
+	CacheMailboxSummaries(userID string, summaries []domain.MailboxSummary, ttl time.Duration) error
+	CacheMessage(message *domain.Message, ttl time.Duration) error
+	CacheMessageList(mailboxID string, messages []domain.Message, ttl time.Duration) error
+	CacheMessageStats(key string, stats *domain.MessageStats, ttl time.Duration) error
+	CacheSession(ctx context.Context, sessionID string, userID string, ttl time.Duration) error
+	CacheStatistics(stats *domain.SystemStatistics, ttl time.Duration) error
+	CacheSystemDomain(sysDomain *domain.SystemDomain, ttl time.Duration) error
+	CacheSystemDomainList(sysDomains []*domain.SystemDomain, ttl time.Duration) error
+	CacheUser(user *domain.User, ttl time.Duration) error
+	Close() error
+	Delete(keys ...cachekey.Key) error
+	DeleteCachedMessages(mailboxID string) error
+	DeleteCachedSession(ctx context.Context, sessionID string) error
+	GetCachedAPIKey(apiKeyID string) (*domain.APIKey, error)
+	GetCachedAPIKeyUser(apiKey string) (string, error)
+	GetCachedConfig() (*domain.SystemConfig, error)
+	GetCachedDefaultSystemDomain() (*domain.SystemDomain, error)
+	GetCachedMailbox(mailboxID string) (*domain.Mailbox, error)
+	GetCachedMailboxSummaries(userID string) ([]domain.MailboxSummary, error)
+	GetCachedMessage(mailboxID, messageID string) (*domain.Message, error)
+	GetCachedMessageList(mailboxID string) ([]domain.Message, error)
+	GetCachedMessageStats(key string) (*domain.MessageStats, error)
+	GetCachedSession(ctx context.Context, sessionID string) (string, error)
+	GetCachedStatistics() (*domain.SystemStatistics, error)
+	GetCachedSystemDomain(domainID string) (*domain.SystemDomain, error)
+	GetCachedSystemDomainList() ([]*domain.SystemDomain, error)
+	GetRateLimit(ctx context.Context, key string) (int64, error)
+	GetSinkStats(ctx context.Context, domainName string) (*domain.SinkStats, error)
+	IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error)
+	IsBlacklisted(ctx context.Context, jti string) (bool, error)
+	IsNotFound(key cachekey.Key) (bool, error)
+	MarkNotFound(key cachekey.Key, ttl time.Duration) error
+	Ping(ctx context.Context) error
+	PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error
+	RecordSinkMessage(ctx context.Context, domainName, sender string, size int64, at time.Time) (int64, error)
+	RecordSinkSample(ctx context.Context, domainName string) error
+	ResetRateLimit(ctx context.Context, key string) error
+	SubscribeNewMail(ctx context.Context) (<-chan *domain.Message, error)
+}
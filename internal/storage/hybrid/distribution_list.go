@@ -0,0 +1,43 @@
+package hybrid
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Distribution List Repository ==========
+//
+// 分发列表直接读写 PostgreSQL：成员变更（包括删除邮箱后的自动移除）需要立即生效。
+
+func (s *Store) SaveDistributionList(ctx context.Context, list *domain.DistributionList) error {
+	return s.postgres.SaveDistributionList(ctx, list)
+}
+
+func (s *Store) GetDistributionList(ctx context.Context, id string) (*domain.DistributionList, error) {
+	return s.postgres.GetDistributionList(ctx, id)
+}
+
+func (s *Store) GetDistributionListByAddress(ctx context.Context, address string) (*domain.DistributionList, error) {
+	return s.postgres.GetDistributionListByAddress(ctx, address)
+}
+
+func (s *Store) ListDistributionListsByUserID(ctx context.Context, userID string) ([]*domain.DistributionList, error) {
+	return s.postgres.ListDistributionListsByUserID(ctx, userID)
+}
+
+func (s *Store) ListDistributionListsByOrgID(ctx context.Context, orgID string) ([]*domain.DistributionList, error) {
+	return s.postgres.ListDistributionListsByOrgID(ctx, orgID)
+}
+
+func (s *Store) DeleteDistributionList(ctx context.Context, id string) error {
+	return s.postgres.DeleteDistributionList(ctx, id)
+}
+
+func (s *Store) SaveDistributionListDelivery(ctx context.Context, delivery *domain.DistributionListDelivery) error {
+	return s.postgres.SaveDistributionListDelivery(ctx, delivery)
+}
+
+func (s *Store) ListDistributionListDeliveries(ctx context.Context, listID string, limit int) ([]*domain.DistributionListDelivery, error) {
+	return s.postgres.ListDistributionListDeliveries(ctx, listID, limit)
+}
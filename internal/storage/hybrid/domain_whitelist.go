@@ -0,0 +1,27 @@
+package hybrid
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Domain Whitelist Repository ==========
+//
+// 白名单直接读写 PostgreSQL，不进入缓存（条目变更需要立即对所有实例的收件检查生效）。
+
+func (s *Store) AddDomainWhitelistEntry(ctx context.Context, entry *domain.DomainWhitelistEntry) error {
+	return s.postgres.AddDomainWhitelistEntry(ctx, entry)
+}
+
+func (s *Store) ListDomainWhitelist(ctx context.Context, domainID string) ([]*domain.DomainWhitelistEntry, error) {
+	return s.postgres.ListDomainWhitelist(ctx, domainID)
+}
+
+func (s *Store) DeleteDomainWhitelistEntry(ctx context.Context, domainID, id string) error {
+	return s.postgres.DeleteDomainWhitelistEntry(ctx, domainID, id)
+}
+
+func (s *Store) IsLocalPartWhitelisted(ctx context.Context, domainID, localPart string) (bool, error) {
+	return s.postgres.IsLocalPartWhitelisted(ctx, domainID, localPart)
+}
@@ -0,0 +1,450 @@
+package hybrid
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/cachekey"
+	"tempmail/backend/internal/storage/postgres"
+)
+
+// 缓存层操作（下标对应 cacheOperations 中的方法名）
+const (
+	cacheOpAddToBlacklist = iota
+	cacheOpCacheAPIKey
+	cacheOpCacheAPIKeyUser
+	cacheOpCacheConfig
+	cacheOpCacheDefaultSystemDomain
+	cacheOpCacheMailbox
+	cacheOpCacheMailboxSummaries
+	cacheOpCacheMessage
+	cacheOpCacheMessageList
+	cacheOpCacheMessageStats
+	cacheOpCacheSession
+	cacheOpCacheStatistics
+	cacheOpCacheSystemDomain
+	cacheOpCacheSystemDomainList
+	cacheOpCacheUser
+	cacheOpClose
+	cacheOpDelete
+	cacheOpDeleteCachedMessages
+	cacheOpDeleteCachedSession
+	cacheOpGetCachedAPIKey
+	cacheOpGetCachedAPIKeyUser
+	cacheOpGetCachedConfig
+	cacheOpGetCachedDefaultSystemDomain
+	cacheOpGetCachedMailbox
+	cacheOpGetCachedMailboxSummaries
+	cacheOpGetCachedMessage
+	cacheOpGetCachedMessageList
+	cacheOpGetCachedMessageStats
+	cacheOpGetCachedSession
+	cacheOpGetCachedStatistics
+	cacheOpGetCachedSystemDomain
+	cacheOpGetCachedSystemDomainList
+	cacheOpGetRateLimit
+	cacheOpGetSinkStats
+	cacheOpIncrementRateLimit
+	cacheOpIsBlacklisted
+	cacheOpIsNotFound
+	cacheOpMarkNotFound
+	cacheOpPing
+	cacheOpPublishNewMail
+	cacheOpRecordSinkMessage
+	cacheOpRecordSinkSample
+	cacheOpResetRateLimit
+	cacheOpSubscribeNewMail
+)
+
+// cacheOperations 缓存层操作下标到方法名（指标标签）
+var cacheOperations = [...]string{
+	cacheOpAddToBlacklist:               "AddToBlacklist",
+	cacheOpCacheAPIKey:                  "CacheAPIKey",
+	cacheOpCacheAPIKeyUser:              "CacheAPIKeyUser",
+	cacheOpCacheConfig:                  "CacheConfig",
+	cacheOpCacheDefaultSystemDomain:     "CacheDefaultSystemDomain",
+	cacheOpCacheMailbox:                 "CacheMailbox",
+	cacheOpCacheMailboxSummaries:        "CacheMailboxSummaries",
+	cacheOpCacheMessage:                 "CacheMessage",
+	cacheOpCacheMessageList:             "CacheMessageList",
+	cacheOpCacheMessageStats:            "CacheMessageStats",
+	cacheOpCacheSession:                 "CacheSession",
+	cacheOpCacheStatistics:              "CacheStatistics",
+	cacheOpCacheSystemDomain:            "CacheSystemDomain",
+	cacheOpCacheSystemDomainList:        "CacheSystemDomainList",
+	cacheOpCacheUser:                    "CacheUser",
+	cacheOpClose:                        "Close",
+	cacheOpDelete:                       "Delete",
+	cacheOpDeleteCachedMessages:         "DeleteCachedMessages",
+	cacheOpDeleteCachedSession:          "DeleteCachedSession",
+	cacheOpGetCachedAPIKey:              "GetCachedAPIKey",
+	cacheOpGetCachedAPIKeyUser:          "GetCachedAPIKeyUser",
+	cacheOpGetCachedConfig:              "GetCachedConfig",
+	cacheOpGetCachedDefaultSystemDomain: "GetCachedDefaultSystemDomain",
+	cacheOpGetCachedMailbox:             "GetCachedMailbox",
+	cacheOpGetCachedMailboxSummaries:    "GetCachedMailboxSummaries",
+	cacheOpGetCachedMessage:             "GetCachedMessage",
+	cacheOpGetCachedMessageList:         "GetCachedMessageList",
+	cacheOpGetCachedMessageStats:        "GetCachedMessageStats",
+	cacheOpGetCachedSession:             "GetCachedSession",
+	cacheOpGetCachedStatistics:          "GetCachedStatistics",
+	cacheOpGetCachedSystemDomain:        "GetCachedSystemDomain",
+	cacheOpGetCachedSystemDomainList:    "GetCachedSystemDomainList",
+	cacheOpGetRateLimit:                 "GetRateLimit",
+	cacheOpGetSinkStats:                 "GetSinkStats",
+	cacheOpIncrementRateLimit:           "IncrementRateLimit",
+	cacheOpIsBlacklisted:                "IsBlacklisted",
+	cacheOpIsNotFound:                   "IsNotFound",
+	cacheOpMarkNotFound:                 "MarkNotFound",
+	cacheOpPing:                         "Ping",
+	cacheOpPublishNewMail:               "PublishNewMail",
+	cacheOpRecordSinkMessage:            "RecordSinkMessage",
+	cacheOpRecordSinkSample:             "RecordSinkSample",
+	cacheOpResetRateLimit:               "ResetRateLimit",
+	cacheOpSubscribeNewMail:             "SubscribeNewMail",
+}
+
+// CacheOperations 返回缓存层全部操作名（按下标排列）
+func CacheOperations() []string {
+	return cacheOperations[:]
+}
+
+// OperationObserver 记录一次缓存层调用（op 为 CacheOperations 中的下标）
+type OperationObserver interface {
+	Observe(op int, start time.Time, err error, key string)
+}
+
+// SetCacheObserver 为缓存层（Redis 或单机模式的进程内缓存）调用计时
+func (s *Store) SetCacheObserver(observer OperationObserver) {
+	s.redis = observedCache{inner: s.redis, observer: observer}
+}
+
+// joinKeys 批量删除时记录的键（逗号分隔）
+func joinKeys(keys []cachekey.Key) string {
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	return strings.Join(names, ",")
+}
+
+// observedCache 为缓存调用计时的包装器（只做透传）
+type observedCache struct {
+	inner    cache
+	observer OperationObserver
+}
+
+func (c observedCache) AddToBlacklist(ctx context.Context, jti string, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.AddToBlacklist(ctx, jti, ttl)
+	c.observer.Observe(cacheOpAddToBlacklist, start, err, jti)
+	return err
+}
+
+func (c observedCache) CacheAPIKey(apiKey *domain.APIKey, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheAPIKey(apiKey, ttl)
+	c.observer.Observe(cacheOpCacheAPIKey, start, err, "")
+	return err
+}
+
+func (c observedCache) CacheAPIKeyUser(apiKey string, userID string, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheAPIKeyUser(apiKey, userID, ttl)
+	c.observer.Observe(cacheOpCacheAPIKeyUser, start, err, apiKey)
+	return err
+}
+
+func (c observedCache) CacheConfig(config *domain.SystemConfig, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheConfig(config, ttl)
+	c.observer.Observe(cacheOpCacheConfig, start, err, "")
+	return err
+}
+
+func (c observedCache) CacheDefaultSystemDomain(sysDomain *domain.SystemDomain, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheDefaultSystemDomain(sysDomain, ttl)
+	c.observer.Observe(cacheOpCacheDefaultSystemDomain, start, err, "")
+	return err
+}
+
+func (c observedCache) CacheMailbox(mailbox *domain.Mailbox, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheMailbox(mailbox, ttl)
+	c.observer.Observe(cacheOpCacheMailbox, start, err, "")
+	return err
+}
+
+func (c observedCache) CacheMailboxSummaries(userID string, summaries []domain.MailboxSummary, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheMailboxSummaries(userID, summaries, ttl)
+	c.observer.Observe(cacheOpCacheMailboxSummaries, start, err, userID)
+	return err
+}
+
+func (c observedCache) CacheMessage(message *domain.Message, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheMessage(message, ttl)
+	c.observer.Observe(cacheOpCacheMessage, start, err, "")
+	return err
+}
+
+func (c observedCache) CacheMessageList(mailboxID string, messages []domain.Message, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheMessageList(mailboxID, messages, ttl)
+	c.observer.Observe(cacheOpCacheMessageList, start, err, mailboxID)
+	return err
+}
+
+func (c observedCache) CacheMessageStats(key string, stats *domain.MessageStats, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheMessageStats(key, stats, ttl)
+	c.observer.Observe(cacheOpCacheMessageStats, start, err, key)
+	return err
+}
+
+func (c observedCache) CacheSession(ctx context.Context, sessionID string, userID string, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheSession(ctx, sessionID, userID, ttl)
+	c.observer.Observe(cacheOpCacheSession, start, err, sessionID)
+	return err
+}
+
+func (c observedCache) CacheStatistics(stats *domain.SystemStatistics, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheStatistics(stats, ttl)
+	c.observer.Observe(cacheOpCacheStatistics, start, err, "")
+	return err
+}
+
+func (c observedCache) CacheSystemDomain(sysDomain *domain.SystemDomain, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheSystemDomain(sysDomain, ttl)
+	c.observer.Observe(cacheOpCacheSystemDomain, start, err, "")
+	return err
+}
+
+func (c observedCache) CacheSystemDomainList(sysDomains []*domain.SystemDomain, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheSystemDomainList(sysDomains, ttl)
+	c.observer.Observe(cacheOpCacheSystemDomainList, start, err, "")
+	return err
+}
+
+func (c observedCache) CacheUser(user *domain.User, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.CacheUser(user, ttl)
+	c.observer.Observe(cacheOpCacheUser, start, err, "")
+	return err
+}
+
+func (c observedCache) Close() error {
+	start := time.Now()
+	err := c.inner.Close()
+	c.observer.Observe(cacheOpClose, start, err, "")
+	return err
+}
+
+func (c observedCache) Delete(keys ...cachekey.Key) error {
+	start := time.Now()
+	err := c.inner.Delete(keys...)
+	c.observer.Observe(cacheOpDelete, start, err, joinKeys(keys))
+	return err
+}
+
+func (c observedCache) DeleteCachedMessages(mailboxID string) error {
+	start := time.Now()
+	err := c.inner.DeleteCachedMessages(mailboxID)
+	c.observer.Observe(cacheOpDeleteCachedMessages, start, err, mailboxID)
+	return err
+}
+
+func (c observedCache) DeleteCachedSession(ctx context.Context, sessionID string) error {
+	start := time.Now()
+	err := c.inner.DeleteCachedSession(ctx, sessionID)
+	c.observer.Observe(cacheOpDeleteCachedSession, start, err, sessionID)
+	return err
+}
+
+func (c observedCache) GetCachedAPIKey(apiKeyID string) (*domain.APIKey, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedAPIKey(apiKeyID)
+	c.observer.Observe(cacheOpGetCachedAPIKey, start, err, apiKeyID)
+	return result, err
+}
+
+func (c observedCache) GetCachedAPIKeyUser(apiKey string) (string, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedAPIKeyUser(apiKey)
+	c.observer.Observe(cacheOpGetCachedAPIKeyUser, start, err, apiKey)
+	return result, err
+}
+
+func (c observedCache) GetCachedConfig() (*domain.SystemConfig, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedConfig()
+	c.observer.Observe(cacheOpGetCachedConfig, start, err, "")
+	return result, err
+}
+
+func (c observedCache) GetCachedDefaultSystemDomain() (*domain.SystemDomain, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedDefaultSystemDomain()
+	c.observer.Observe(cacheOpGetCachedDefaultSystemDomain, start, err, "")
+	return result, err
+}
+
+func (c observedCache) GetCachedMailbox(mailboxID string) (*domain.Mailbox, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedMailbox(mailboxID)
+	c.observer.Observe(cacheOpGetCachedMailbox, start, err, mailboxID)
+	return result, err
+}
+
+func (c observedCache) GetCachedMailboxSummaries(userID string) ([]domain.MailboxSummary, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedMailboxSummaries(userID)
+	c.observer.Observe(cacheOpGetCachedMailboxSummaries, start, err, userID)
+	return result, err
+}
+
+func (c observedCache) GetCachedMessage(mailboxID string, messageID string) (*domain.Message, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedMessage(mailboxID, messageID)
+	c.observer.Observe(cacheOpGetCachedMessage, start, err, mailboxID)
+	return result, err
+}
+
+func (c observedCache) GetCachedMessageList(mailboxID string) ([]domain.Message, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedMessageList(mailboxID)
+	c.observer.Observe(cacheOpGetCachedMessageList, start, err, mailboxID)
+	return result, err
+}
+
+func (c observedCache) GetCachedMessageStats(key string) (*domain.MessageStats, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedMessageStats(key)
+	c.observer.Observe(cacheOpGetCachedMessageStats, start, err, key)
+	return result, err
+}
+
+func (c observedCache) GetCachedSession(ctx context.Context, sessionID string) (string, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedSession(ctx, sessionID)
+	c.observer.Observe(cacheOpGetCachedSession, start, err, sessionID)
+	return result, err
+}
+
+func (c observedCache) GetCachedStatistics() (*domain.SystemStatistics, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedStatistics()
+	c.observer.Observe(cacheOpGetCachedStatistics, start, err, "")
+	return result, err
+}
+
+func (c observedCache) GetCachedSystemDomain(domainID string) (*domain.SystemDomain, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedSystemDomain(domainID)
+	c.observer.Observe(cacheOpGetCachedSystemDomain, start, err, domainID)
+	return result, err
+}
+
+func (c observedCache) GetCachedSystemDomainList() ([]*domain.SystemDomain, error) {
+	start := time.Now()
+	result, err := c.inner.GetCachedSystemDomainList()
+	c.observer.Observe(cacheOpGetCachedSystemDomainList, start, err, "")
+	return result, err
+}
+
+func (c observedCache) GetRateLimit(ctx context.Context, key string) (int64, error) {
+	start := time.Now()
+	result, err := c.inner.GetRateLimit(ctx, key)
+	c.observer.Observe(cacheOpGetRateLimit, start, err, key)
+	return result, err
+}
+
+func (c observedCache) GetSinkStats(ctx context.Context, domainName string) (*domain.SinkStats, error) {
+	start := time.Now()
+	result, err := c.inner.GetSinkStats(ctx, domainName)
+	c.observer.Observe(cacheOpGetSinkStats, start, err, domainName)
+	return result, err
+}
+
+func (c observedCache) IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error) {
+	start := time.Now()
+	result, err := c.inner.IncrementRateLimit(ctx, key, window)
+	c.observer.Observe(cacheOpIncrementRateLimit, start, err, key)
+	return result, err
+}
+
+func (c observedCache) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
+	start := time.Now()
+	result, err := c.inner.IsBlacklisted(ctx, jti)
+	c.observer.Observe(cacheOpIsBlacklisted, start, err, jti)
+	return result, err
+}
+
+func (c observedCache) IsNotFound(key cachekey.Key) (bool, error) {
+	start := time.Now()
+	result, err := c.inner.IsNotFound(key)
+	c.observer.Observe(cacheOpIsNotFound, start, err, key.String())
+	return result, err
+}
+
+func (c observedCache) MarkNotFound(key cachekey.Key, ttl time.Duration) error {
+	start := time.Now()
+	err := c.inner.MarkNotFound(key, ttl)
+	c.observer.Observe(cacheOpMarkNotFound, start, err, key.String())
+	return err
+}
+
+func (c observedCache) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := c.inner.Ping(ctx)
+	c.observer.Observe(cacheOpPing, start, err, "")
+	return err
+}
+
+func (c observedCache) PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error {
+	start := time.Now()
+	err := c.inner.PublishNewMail(ctx, mailboxID, message)
+	c.observer.Observe(cacheOpPublishNewMail, start, err, mailboxID)
+	return err
+}
+
+func (c observedCache) RecordSinkMessage(ctx context.Context, domainName string, sender string, size int64, at time.Time) (int64, error) {
+	start := time.Now()
+	result, err := c.inner.RecordSinkMessage(ctx, domainName, sender, size, at)
+	c.observer.Observe(cacheOpRecordSinkMessage, start, err, domainName)
+	return result, err
+}
+
+func (c observedCache) RecordSinkSample(ctx context.Context, domainName string) error {
+	start := time.Now()
+	err := c.inner.RecordSinkSample(ctx, domainName)
+	c.observer.Observe(cacheOpRecordSinkSample, start, err, domainName)
+	return err
+}
+
+func (c observedCache) ResetRateLimit(ctx context.Context, key string) error {
+	start := time.Now()
+	err := c.inner.ResetRateLimit(ctx, key)
+	c.observer.Observe(cacheOpResetRateLimit, start, err, key)
+	return err
+}
+
+func (c observedCache) SubscribeNewMail(ctx context.Context) (<-chan *domain.Message, error) {
+	start := time.Now()
+	result, err := c.inner.SubscribeNewMail(ctx)
+	c.observer.Observe(cacheOpSubscribeNewMail, start, err, "")
+	return result, err
+}
+
+// SetSlowQueryLog 开启数据库慢 SQL 记录
+func (s *Store) SetSlowQueryLog(threshold time.Duration, sink postgres.SlowQuerySink) {
+	s.postgres.SetSlowQueryLog(threshold, sink)
+}
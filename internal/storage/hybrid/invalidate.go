@@ -0,0 +1,79 @@
+package hybrid
+
+import (
+	"context"
+	"errors"
+
+	"tempmail/backend/internal/storage/cachekey"
+)
+
+// ========== 缓存失效 ==========
+//
+// 每个写操作在数据库写入成功后调用下面的 invalidate* 方法，由它们决定一个实体变化时
+// 要失效哪些缓存键，写操作本身不再拼接键名。事务中的 Store 使用 txCache，失效在提交后执行。
+// 失效失败只会让缓存在 TTL 内保持旧值，不影响已提交的写入。
+
+// invalidate 失效一组缓存键（一次往返），负缓存键先递增代数，正在回源的查询不再写入
+func (s *Store) invalidate(keys ...cachekey.Key) error {
+	s.negatives.bump(keys...)
+	return s.redis.Delete(keys...)
+}
+
+// mailboxKeys 邮箱实体及所属用户摘要的缓存键（owner 为空表示游客邮箱或已不存在）
+func mailboxKeys(mailboxID, owner string) []cachekey.Key {
+	keys := []cachekey.Key{cachekey.Mailbox(mailboxID)}
+	if owner != "" {
+		keys = append(keys, cachekey.MailboxSummaries(owner))
+	}
+	return keys
+}
+
+// invalidateMailbox 失效邮箱实体和所属用户的邮箱摘要
+func (s *Store) invalidateMailbox(ctx context.Context, mailboxID string) error {
+	return s.invalidate(mailboxKeys(mailboxID, s.mailboxOwner(ctx, mailboxID))...)
+}
+
+// invalidateMessages 失效单封邮件、邮箱的邮件列表和所属用户的邮箱摘要（摘要包含未读数和最近邮件预览）
+func (s *Store) invalidateMessages(ctx context.Context, mailboxID string, messageIDs ...string) error {
+	keys := make([]cachekey.Key, 0, len(messageIDs)+2)
+	keys = append(keys, cachekey.MessageList(mailboxID))
+	for _, messageID := range messageIDs {
+		keys = append(keys, cachekey.Message(mailboxID, messageID))
+	}
+	if owner := s.mailboxOwner(ctx, mailboxID); owner != "" {
+		keys = append(keys, cachekey.MailboxSummaries(owner))
+	}
+	return s.invalidate(keys...)
+}
+
+// EvictMailboxCache 清除邮箱相关的全部缓存：邮箱、邮件列表和单封邮件
+func (s *Store) EvictMailboxCache(mailboxID string) error {
+	return errors.Join(
+		s.invalidate(cachekey.Mailbox(mailboxID), cachekey.MessageList(mailboxID)),
+		s.redis.DeleteCachedMessages(mailboxID),
+	)
+}
+
+// invalidateSystemDomains 失效系统域名实体、列表和默认域名
+//
+// 列表和默认域名总是一起失效：任何域名的状态、计数或默认标记变化都可能出现在这两份缓存里。
+// names 为写入后的域名，清除它们的负缓存，新域名立即可见。
+func (s *Store) invalidateSystemDomains(domainIDs []string, names ...string) error {
+	keys := []cachekey.Key{cachekey.SystemDomainList(), cachekey.DefaultSystemDomain()}
+	for _, domainID := range domainIDs {
+		keys = append(keys, cachekey.SystemDomain(domainID))
+	}
+	for _, name := range names {
+		keys = append(keys, cachekey.NotFound(cachekey.SystemDomainName(name)))
+	}
+	return s.invalidate(keys...)
+}
+
+// invalidateUser 失效用户实体，以及指向该用户的 API Key 映射
+func (s *Store) invalidateUser(userID string, apiKeys ...string) error {
+	keys := []cachekey.Key{cachekey.User(userID)}
+	for _, apiKey := range apiKeys {
+		keys = append(keys, cachekey.APIKeyUser(apiKey))
+	}
+	return s.invalidate(keys...)
+}
@@ -0,0 +1,195 @@
+package hybrid
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+	"tempmail/backend/internal/storage/cachekey"
+)
+
+// errLocalCacheMiss 单机模式不缓存实体，所有读取都回源数据库
+var errLocalCacheMiss = errors.New("local cache miss")
+
+// Local 单机模式下代替 Redis 的进程内能力（*memory.Store 实现）
+//
+// 这些数据在 Redis 模式下也不落库：黑名单、限流、会话、系统配置和黑洞统计。
+// 单机模式下它们只保存在进程内，重启后丢失。
+type Local interface {
+	domain.SinkStatsRepository
+
+	AddToBlacklist(ctx context.Context, jti string, ttl time.Duration) error
+	CacheSession(ctx context.Context, sessionID string, userID string, ttl time.Duration) error
+	DeleteCachedSession(ctx context.Context, sessionID string) error
+	GetCachedSession(ctx context.Context, sessionID string) (string, error)
+	GetRateLimit(ctx context.Context, key string) (int64, error)
+	GetSystemConfig(ctx context.Context) (*domain.SystemConfig, error)
+	IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error)
+	IsBlacklisted(ctx context.Context, jti string) (bool, error)
+	ResetRateLimit(ctx context.Context, key string) error
+	SaveSystemConfig(ctx context.Context, config *domain.SystemConfig) error
+}
+
+// localCache 以进程内能力实现 cache 接口
+//
+// 实体缓存和负缓存全部是空操作（单连接的 SQLite 本身就在本机，缓存收益很小），
+// 发布订阅也是空操作：单实例没有其他订阅者。
+type localCache struct {
+	local Local
+}
+
+func (c localCache) AddToBlacklist(ctx context.Context, jti string, ttl time.Duration) error {
+	return c.local.AddToBlacklist(ctx, jti, ttl)
+}
+
+func (c localCache) CacheAPIKey(apiKey *domain.APIKey, ttl time.Duration) error { return nil }
+
+func (c localCache) CacheAPIKeyUser(apiKey, userID string, ttl time.Duration) error { return nil }
+
+func (c localCache) CacheConfig(config *domain.SystemConfig, ttl time.Duration) error {
+	return c.local.SaveSystemConfig(context.Background(), config)
+}
+
+func (c localCache) CacheDefaultSystemDomain(sysDomain *domain.SystemDomain, ttl time.Duration) error {
+	return nil
+}
+
+func (c localCache) CacheMailbox(mailbox *domain.Mailbox, ttl time.Duration) error { return nil }
+
+func (c localCache) CacheMailboxSummaries(userID string, summaries []domain.MailboxSummary, ttl time.Duration) error {
+	return nil
+}
+
+func (c localCache) CacheMessage(message *domain.Message, ttl time.Duration) error { return nil }
+
+func (c localCache) CacheMessageList(mailboxID string, messages []domain.Message, ttl time.Duration) error {
+	return nil
+}
+
+func (c localCache) CacheMessageStats(key string, stats *domain.MessageStats, ttl time.Duration) error {
+	return nil
+}
+
+func (c localCache) CacheSession(ctx context.Context, sessionID string, userID string, ttl time.Duration) error {
+	return c.local.CacheSession(ctx, sessionID, userID, ttl)
+}
+
+func (c localCache) CacheStatistics(stats *domain.SystemStatistics, ttl time.Duration) error {
+	return nil
+}
+
+func (c localCache) CacheSystemDomain(sysDomain *domain.SystemDomain, ttl time.Duration) error {
+	return nil
+}
+
+func (c localCache) CacheSystemDomainList(sysDomains []*domain.SystemDomain, ttl time.Duration) error {
+	return nil
+}
+
+func (c localCache) CacheUser(user *domain.User, ttl time.Duration) error { return nil }
+
+func (c localCache) Close() error { return nil }
+
+func (c localCache) Ping(ctx context.Context) error { return nil }
+
+func (c localCache) Delete(keys ...cachekey.Key) error { return nil }
+
+func (c localCache) DeleteCachedMessages(mailboxID string) error { return nil }
+
+func (c localCache) DeleteCachedSession(ctx context.Context, sessionID string) error {
+	return c.local.DeleteCachedSession(ctx, sessionID)
+}
+
+func (c localCache) GetCachedAPIKey(apiKeyID string) (*domain.APIKey, error) {
+	return nil, errLocalCacheMiss
+}
+
+func (c localCache) GetCachedAPIKeyUser(apiKey string) (string, error) {
+	return "", errLocalCacheMiss
+}
+
+func (c localCache) GetCachedConfig() (*domain.SystemConfig, error) {
+	return c.local.GetSystemConfig(context.Background())
+}
+
+func (c localCache) GetCachedDefaultSystemDomain() (*domain.SystemDomain, error) {
+	return nil, errLocalCacheMiss
+}
+
+func (c localCache) GetCachedMailbox(mailboxID string) (*domain.Mailbox, error) {
+	return nil, errLocalCacheMiss
+}
+
+func (c localCache) GetCachedMailboxSummaries(userID string) ([]domain.MailboxSummary, error) {
+	return nil, errLocalCacheMiss
+}
+
+func (c localCache) GetCachedMessage(mailboxID, messageID string) (*domain.Message, error) {
+	return nil, errLocalCacheMiss
+}
+
+func (c localCache) GetCachedMessageList(mailboxID string) ([]domain.Message, error) {
+	return nil, errLocalCacheMiss
+}
+
+func (c localCache) GetCachedMessageStats(key string) (*domain.MessageStats, error) {
+	return nil, errLocalCacheMiss
+}
+
+func (c localCache) GetCachedSession(ctx context.Context, sessionID string) (string, error) {
+	return c.local.GetCachedSession(ctx, sessionID)
+}
+
+func (c localCache) GetCachedStatistics() (*domain.SystemStatistics, error) {
+	return nil, errLocalCacheMiss
+}
+
+func (c localCache) GetCachedSystemDomain(domainID string) (*domain.SystemDomain, error) {
+	return nil, errLocalCacheMiss
+}
+
+func (c localCache) GetCachedSystemDomainList() ([]*domain.SystemDomain, error) {
+	return nil, errLocalCacheMiss
+}
+
+func (c localCache) GetRateLimit(ctx context.Context, key string) (int64, error) {
+	return c.local.GetRateLimit(ctx, key)
+}
+
+func (c localCache) GetSinkStats(ctx context.Context, domainName string) (*domain.SinkStats, error) {
+	return c.local.GetSinkStats(ctx, domainName)
+}
+
+func (c localCache) IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return c.local.IncrementRateLimit(ctx, key, window)
+}
+
+func (c localCache) ResetRateLimit(ctx context.Context, key string) error {
+	return c.local.ResetRateLimit(ctx, key)
+}
+
+func (c localCache) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
+	return c.local.IsBlacklisted(ctx, jti)
+}
+
+func (c localCache) IsNotFound(key cachekey.Key) (bool, error) { return false, nil }
+
+func (c localCache) MarkNotFound(key cachekey.Key, ttl time.Duration) error { return nil }
+
+func (c localCache) PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error {
+	return nil
+}
+
+func (c localCache) RecordSinkMessage(ctx context.Context, domainName, sender string, size int64, at time.Time) (int64, error) {
+	return c.local.RecordSinkMessage(ctx, domainName, sender, size, at)
+}
+
+func (c localCache) RecordSinkSample(ctx context.Context, domainName string) error {
+	return c.local.RecordSinkSample(ctx, domainName)
+}
+
+func (c localCache) SubscribeNewMail(ctx context.Context) (<-chan *domain.Message, error) {
+	return nil, storage.ErrPubSubUnavailable
+}
@@ -0,0 +1,36 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Forward Repository ==========
+//
+// 转发地址和投递队列直接读写 PostgreSQL，不进入缓存（任一实例收信时都要读到最新的确认状态）。
+
+func (s *Store) SaveMailboxForward(ctx context.Context, forward *domain.MailboxForward) error {
+	return s.postgres.SaveMailboxForward(ctx, forward)
+}
+
+func (s *Store) GetMailboxForward(ctx context.Context, mailboxID, id string) (*domain.MailboxForward, error) {
+	return s.postgres.GetMailboxForward(ctx, mailboxID, id)
+}
+
+func (s *Store) ListMailboxForwards(ctx context.Context, mailboxID string) ([]*domain.MailboxForward, error) {
+	return s.postgres.ListMailboxForwards(ctx, mailboxID)
+}
+
+func (s *Store) DeleteMailboxForward(ctx context.Context, mailboxID, id string) error {
+	return s.postgres.DeleteMailboxForward(ctx, mailboxID, id)
+}
+
+func (s *Store) SaveForwardDelivery(ctx context.Context, delivery *domain.ForwardDelivery) error {
+	return s.postgres.SaveForwardDelivery(ctx, delivery)
+}
+
+func (s *Store) ListPendingForwardDeliveries(ctx context.Context, now time.Time, limit int) ([]*domain.ForwardDelivery, error) {
+	return s.postgres.ListPendingForwardDeliveries(ctx, now, limit)
+}
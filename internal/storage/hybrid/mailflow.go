@@ -0,0 +1,24 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Mail Flow Repository ==========
+//
+// 收信流量计数由 SMTP 后端在内存中合并后定期写入，直接读写 PostgreSQL，不进入缓存。
+
+func (s *Store) MergeMailFlowCounters(ctx context.Context, counters []domain.MailFlowCounter) error {
+	return s.postgres.MergeMailFlowCounters(ctx, counters)
+}
+
+func (s *Store) ListMailFlowCounters(ctx context.Context, since, until time.Time) ([]domain.MailFlowCounter, error) {
+	return s.postgres.ListMailFlowCounters(ctx, since, until)
+}
+
+func (s *Store) DeleteMailFlowCountersBefore(ctx context.Context, before time.Time) (int64, error) {
+	return s.postgres.DeleteMailFlowCountersBefore(ctx, before)
+}
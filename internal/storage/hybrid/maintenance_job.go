@@ -0,0 +1,66 @@
+package hybrid
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Maintenance Job Repository ==========
+//
+// 维护任务直接读写 PostgreSQL，不进入缓存；修正数据后失效对应的邮件和邮箱缓存。
+
+func (s *Store) CreateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error {
+	return s.postgres.CreateMaintenanceJob(ctx, job)
+}
+
+func (s *Store) GetMaintenanceJob(ctx context.Context, id string) (*domain.MaintenanceJob, error) {
+	return s.postgres.GetMaintenanceJob(ctx, id)
+}
+
+func (s *Store) ListMaintenanceJobs(ctx context.Context) ([]*domain.MaintenanceJob, error) {
+	return s.postgres.ListMaintenanceJobs(ctx)
+}
+
+func (s *Store) UpdateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error {
+	return s.postgres.UpdateMaintenanceJob(ctx, job)
+}
+
+func (s *Store) CountMessages(ctx context.Context) (int64, error) {
+	return s.postgres.CountMessages(ctx)
+}
+
+func (s *Store) ListMessagesAfter(ctx context.Context, afterID string, limit int) ([]domain.Message, error) {
+	return s.postgres.ListMessagesAfter(ctx, afterID, limit)
+}
+
+// SetMessageDerivedText 标记邮件已有生成的纯文本，并失效邮件缓存
+func (s *Store) SetMessageDerivedText(ctx context.Context, mailboxID, messageID, text string) error {
+	if err := s.postgres.SetMessageDerivedText(ctx, mailboxID, messageID, text); err != nil {
+		return err
+	}
+
+	s.invalidateMessages(ctx, mailboxID, messageID) // 摘要包含最近邮件预览
+
+	return nil
+}
+
+func (s *Store) CountMailboxes(ctx context.Context) (int64, error) {
+	return s.postgres.CountMailboxes(ctx)
+}
+
+func (s *Store) ListMailboxIDsAfter(ctx context.Context, afterID string, limit int) ([]string, error) {
+	return s.postgres.ListMailboxIDsAfter(ctx, afterID, limit)
+}
+
+// RecountMailbox 重新统计邮箱数量，统计有变化时失效邮箱缓存
+func (s *Store) RecountMailbox(ctx context.Context, mailboxID string) (bool, error) {
+	changed, err := s.postgres.RecountMailbox(ctx, mailboxID)
+	if err != nil || !changed {
+		return changed, err
+	}
+
+	s.invalidateMailbox(ctx, mailboxID)
+
+	return true, nil
+}
@@ -0,0 +1,28 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Message Share Repository ==========
+//
+// 分享链接直接读写 PostgreSQL，不进入缓存（撤销需要立即生效）。
+
+func (s *Store) SaveMessageShare(ctx context.Context, share *domain.MessageShare) error {
+	return s.postgres.SaveMessageShare(ctx, share)
+}
+
+func (s *Store) GetMessageShare(ctx context.Context, id string) (*domain.MessageShare, error) {
+	return s.postgres.GetMessageShare(ctx, id)
+}
+
+func (s *Store) ListMessageShares(ctx context.Context, mailboxID string) ([]*domain.MessageShare, error) {
+	return s.postgres.ListMessageShares(ctx, mailboxID)
+}
+
+func (s *Store) RecordMessageShareView(ctx context.Context, id string, at time.Time) error {
+	return s.postgres.RecordMessageShareView(ctx, id, at)
+}
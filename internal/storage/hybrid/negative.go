@@ -0,0 +1,78 @@
+package hybrid
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"tempmail/backend/internal/storage/cachekey"
+)
+
+// negativeGuardBuckets 负缓存代数的分桶数
+const negativeGuardBuckets = 256
+
+// negativeGuard 防止回源期间发生的写入被过期的负缓存覆盖
+//
+// 未命中的查询先记下负缓存键的代数再查库；写入后失效负缓存时递增代数，查询结束时代数已变化
+// （查库与失效交错）则不写入“不存在”。代数按键哈希分桶，不随键数增长，桶冲突只会少写一次负缓存。
+// 代数只在本进程内有效，其他实例的写入仍依靠失效时删除负缓存。
+type negativeGuard struct {
+	buckets [negativeGuardBuckets]negativeBucket
+}
+
+type negativeBucket struct {
+	mu         sync.Mutex
+	generation uint64
+}
+
+func newNegativeGuard() *negativeGuard {
+	return &negativeGuard{}
+}
+
+func (g *negativeGuard) bucket(key cachekey.Key) *negativeBucket {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &g.buckets[h.Sum32()%negativeGuardBuckets]
+}
+
+// generation 查库前记下负缓存键当前的代数
+func (g *negativeGuard) generation(key cachekey.Key) uint64 {
+	if g == nil {
+		return 0
+	}
+	b := g.bucket(cachekey.NotFound(key))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.generation
+}
+
+// bump 递增被失效的负缓存键的代数（其他命名空间的键忽略）
+func (g *negativeGuard) bump(keys ...cachekey.Key) {
+	if g == nil {
+		return
+	}
+	for _, key := range keys {
+		if key.Namespace() != cachekey.NamespaceNotFound {
+			continue
+		}
+		b := g.bucket(key)
+		b.mu.Lock()
+		b.generation++
+		b.mu.Unlock()
+	}
+}
+
+// markNotFound 代数仍为查库前的值时写入负缓存
+//
+// 检查和写入在同一把锁内完成，与之交错的失效要么先递增代数（跳过写入），要么在写入之后删除它。
+func (s *Store) markNotFound(key cachekey.Key, generation uint64) {
+	if s.negatives == nil {
+		s.redis.MarkNotFound(key, negativeCacheTTL)
+		return
+	}
+	b := s.negatives.bucket(cachekey.NotFound(key))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.generation == generation {
+		s.redis.MarkNotFound(key, negativeCacheTTL)
+	}
+}
@@ -0,0 +1,28 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== OAuth Identity Repository ==========
+//
+// 第三方登录身份只在登录回调时读写，直接访问 PostgreSQL，不进入缓存。
+
+func (s *Store) CreateOAuthIdentity(ctx context.Context, identity *domain.OAuthIdentity) error {
+	return s.postgres.CreateOAuthIdentity(ctx, identity)
+}
+
+func (s *Store) GetOAuthIdentity(ctx context.Context, provider, subject string) (*domain.OAuthIdentity, error) {
+	return s.postgres.GetOAuthIdentity(ctx, provider, subject)
+}
+
+func (s *Store) TouchOAuthIdentity(ctx context.Context, id string, at time.Time) error {
+	return s.postgres.TouchOAuthIdentity(ctx, id, at)
+}
+
+func (s *Store) ListOAuthIdentities(ctx context.Context, userID string) ([]*domain.OAuthIdentity, error) {
+	return s.postgres.ListOAuthIdentities(ctx, userID)
+}
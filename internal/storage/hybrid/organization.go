@@ -0,0 +1,52 @@
+package hybrid
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Organization Repository ==========
+//
+// 组织与成员关系直接读写 PostgreSQL：授权判断依赖成员关系，
+// 移除成员必须立即生效，不经过缓存。
+
+func (s *Store) CreateOrganization(ctx context.Context, org *domain.Organization) error {
+	return s.postgres.CreateOrganization(ctx, org)
+}
+
+func (s *Store) GetOrganization(ctx context.Context, id string) (*domain.Organization, error) {
+	return s.postgres.GetOrganization(ctx, id)
+}
+
+func (s *Store) SaveOrgMember(ctx context.Context, member *domain.OrgMember) error {
+	return s.postgres.SaveOrgMember(ctx, member)
+}
+
+func (s *Store) GetOrgMember(ctx context.Context, orgID, userID string) (*domain.OrgMember, error) {
+	return s.postgres.GetOrgMember(ctx, orgID, userID)
+}
+
+func (s *Store) ListOrgMembers(ctx context.Context, orgID string) ([]*domain.OrgMember, error) {
+	return s.postgres.ListOrgMembers(ctx, orgID)
+}
+
+func (s *Store) ListOrgMembershipsByUserID(ctx context.Context, userID string) ([]*domain.OrgMember, error) {
+	return s.postgres.ListOrgMembershipsByUserID(ctx, userID)
+}
+
+func (s *Store) DeleteOrgMember(ctx context.Context, orgID, userID string) error {
+	return s.postgres.DeleteOrgMember(ctx, orgID, userID)
+}
+
+func (s *Store) SaveOrgInvite(ctx context.Context, invite *domain.OrgInvite) error {
+	return s.postgres.SaveOrgInvite(ctx, invite)
+}
+
+func (s *Store) GetOrgInvite(ctx context.Context, token string) (*domain.OrgInvite, error) {
+	return s.postgres.GetOrgInvite(ctx, token)
+}
+
+func (s *Store) DeleteOrgInvite(ctx context.Context, token string) error {
+	return s.postgres.DeleteOrgInvite(ctx, token)
+}
@@ -0,0 +1,33 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Outbox Repository ==========
+//
+// 发件箱事件只由分发器按条件认领，直接访问数据库，不进入缓存；邮件部分与 SaveMessages 一样更新缓存。
+
+// SaveMessagesWithOutbox 在同一事务内保存邮件和发件箱事件，提交后更新邮件缓存
+func (s *Store) SaveMessagesWithOutbox(ctx context.Context, messages []*domain.Message, events []*domain.OutboxEvent) error {
+	if err := s.postgres.SaveMessagesWithOutbox(ctx, messages, events); err != nil {
+		return err
+	}
+	s.cacheSavedMessages(ctx, messages)
+	return nil
+}
+
+func (s *Store) ClaimOutboxEvents(ctx context.Context, now, until time.Time, limit int) ([]*domain.OutboxEvent, error) {
+	return s.postgres.ClaimOutboxEvents(ctx, now, until, limit)
+}
+
+func (s *Store) UpdateOutboxEvent(ctx context.Context, event *domain.OutboxEvent) error {
+	return s.postgres.UpdateOutboxEvent(ctx, event)
+}
+
+func (s *Store) DeleteProcessedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	return s.postgres.DeleteProcessedOutboxEvents(ctx, before)
+}
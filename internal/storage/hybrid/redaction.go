@@ -0,0 +1,19 @@
+package hybrid
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Redaction Repository ==========
+//
+// 脱敏副本直接读写 PostgreSQL，不进入缓存。
+
+func (s *Store) SaveMessageRedaction(ctx context.Context, redaction *domain.MessageRedaction) error {
+	return s.postgres.SaveMessageRedaction(ctx, redaction)
+}
+
+func (s *Store) GetMessageRedaction(ctx context.Context, mailboxID, messageID string) (*domain.MessageRedaction, error) {
+	return s.postgres.GetMessageRedaction(ctx, mailboxID, messageID)
+}
@@ -0,0 +1,27 @@
+package hybrid
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Reserved Prefix Repository ==========
+//
+// 保留前缀直接读写 PostgreSQL，不进入缓存（变更需要立即对所有实例的邮箱创建检查生效）。
+
+func (s *Store) CreateReservedPrefix(ctx context.Context, prefix *domain.ReservedPrefix) error {
+	return s.postgres.CreateReservedPrefix(ctx, prefix)
+}
+
+func (s *Store) ListReservedPrefixes(ctx context.Context, userID *string) ([]*domain.ReservedPrefix, error) {
+	return s.postgres.ListReservedPrefixes(ctx, userID)
+}
+
+func (s *Store) FindReservedPrefixes(ctx context.Context, domainName, localPart string) ([]*domain.ReservedPrefix, error) {
+	return s.postgres.FindReservedPrefixes(ctx, domainName, localPart)
+}
+
+func (s *Store) DeleteReservedPrefix(ctx context.Context, id string) error {
+	return s.postgres.DeleteReservedPrefix(ctx, id)
+}
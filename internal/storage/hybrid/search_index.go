@@ -0,0 +1,23 @@
+package hybrid
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Search Index Repository ==========
+//
+// 全文索引直接读写数据库，查询结果不缓存（新邮件入库后立即可搜）。
+
+func (s *Store) IndexMessages(ctx context.Context, docs []*domain.SearchDocument) error {
+	return s.postgres.IndexMessages(ctx, docs)
+}
+
+func (s *Store) SearchIndex(ctx context.Context, query domain.IndexQuery) (*domain.MessageSearchResult, error) {
+	return s.postgres.SearchIndex(ctx, query)
+}
+
+func (s *Store) ClearSearchIndex(ctx context.Context) error {
+	return s.postgres.ClearSearchIndex(ctx)
+}
@@ -0,0 +1,23 @@
+package hybrid
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Sent Message Repository ==========
+//
+// 已发送邮件直接读写 PostgreSQL，不进入缓存（配额统计需要看到其他实例刚发出的邮件）。
+
+func (s *Store) SaveSentMessage(ctx context.Context, message *domain.SentMessage) error {
+	return s.postgres.SaveSentMessage(ctx, message)
+}
+
+func (s *Store) ListSentMessages(ctx context.Context, mailboxID string) ([]*domain.SentMessage, error) {
+	return s.postgres.ListSentMessages(ctx, mailboxID)
+}
+
+func (s *Store) CountSentMessages(ctx context.Context, filter domain.SentMessageFilter) (domain.SentMessageUsage, error) {
+	return s.postgres.CountSentMessages(ctx, filter)
+}
@@ -0,0 +1,24 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Sink Stats Repository ==========
+//
+// 黑洞模式只累计计数，全部保存在 Redis，不写入 PostgreSQL。
+
+func (s *Store) RecordSinkMessage(ctx context.Context, domainName, sender string, size int64, at time.Time) (int64, error) {
+	return s.redis.RecordSinkMessage(ctx, domainName, sender, size, at)
+}
+
+func (s *Store) RecordSinkSample(ctx context.Context, domainName string) error {
+	return s.redis.RecordSinkSample(ctx, domainName)
+}
+
+func (s *Store) GetSinkStats(ctx context.Context, domainName string) (*domain.SinkStats, error) {
+	return s.redis.GetSinkStats(ctx, domainName)
+}
@@ -0,0 +1,73 @@
+package hybrid
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestStandaloneStore_SQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tempmail.db")
+
+	store, err := NewStandaloneStore("sqlite", path, memory.NewStore(time.Hour))
+	require.NoError(t, err)
+
+	mailbox := &domain.Mailbox{
+		ID: "mb-1", Address: "inbox@corp.example", LocalPart: "inbox", Domain: "corp.example",
+		Token: "tok-1", CreatedAt: time.Now(),
+	}
+	require.NoError(t, store.SaveMailbox(t.Context(), mailbox))
+	message := &domain.Message{ID: "msg-1", MailboxID: mailbox.ID, Subject: "hello", ReceivedAt: time.Now(), CreatedAt: time.Now()}
+	require.NoError(t, store.SaveMessage(t.Context(), message))
+
+	t.Run("Redis 能力由进程内实现", func(t *testing.T) {
+		require.NoError(t, store.AddToBlacklist(t.Context(), "jti-1", time.Hour))
+		blacklisted, err := store.IsBlacklisted(t.Context(), "jti-1")
+		require.NoError(t, err)
+		assert.True(t, blacklisted)
+
+		count, err := store.IncrementRateLimit(t.Context(), "ip:1", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		total, err := store.RecordSinkMessage(t.Context(), "sink.example", "a@b.example", 10, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+	})
+
+	t.Run("未命中的地址不做负缓存", func(t *testing.T) {
+		_, err := store.GetMailboxByAddress(t.Context(), "later@corp.example")
+		assert.Error(t, err)
+
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+			ID: "mb-2", Address: "later@corp.example", LocalPart: "later", Domain: "corp.example",
+			Token: "tok-2", CreatedAt: time.Now(),
+		}))
+		got, err := store.GetMailboxByAddress(t.Context(), "later@corp.example")
+		require.NoError(t, err)
+		assert.Equal(t, "mb-2", got.ID)
+	})
+
+	require.NoError(t, store.Close())
+
+	t.Run("重启后数据仍在", func(t *testing.T) {
+		reopened, err := NewStandaloneStore("sqlite", path, memory.NewStore(time.Hour))
+		require.NoError(t, err)
+		defer reopened.Close()
+
+		got, err := reopened.GetMailboxByAddress(t.Context(), "inbox@corp.example")
+		require.NoError(t, err)
+		assert.Equal(t, mailbox.ID, got.ID)
+
+		messages, err := reopened.ListMessages(t.Context(), mailbox.ID)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "hello", messages[0].Subject)
+	})
+}
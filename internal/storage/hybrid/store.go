@@ -2,19 +2,37 @@ package hybrid
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"golang.org/x/sync/singleflight"
+
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/cachekey"
+	"tempmail/backend/internal/storage/mongo"
 	"tempmail/backend/internal/storage/postgres"
 	"tempmail/backend/internal/storage/redis"
 )
 
+// ErrCacheUnavailable 数据库已连通但 Redis 不可用，调用方可退回进程内缓存
+var ErrCacheUnavailable = errors.New("redis cache unavailable")
+
+// ErrUnsupportedDatabase 配置了不支持的数据库类型
+var ErrUnsupportedDatabase = errors.New("unsupported database type")
+
 // Store 混合存储实现，结合 PostgreSQL 和 Redis
 type Store struct {
-	postgres *postgres.Store
-	redis    *redis.Cache
+	postgres database
+	redis    cache
 	ctx      context.Context
+
+	group     singleflight.Group // 合并并发的缓存未命中查询
+	negatives *negativeGuard     // 回源与写入交错时不写入过期的负缓存（事务中与外层共用）
+	metrics   CacheMetrics
 }
 
 // NewStore 创建混合存储实例 (PostgreSQL)
@@ -24,111 +42,280 @@ func NewStore(postgresDSN, redisAddr, redisPassword string, redisDB int) (*Store
 
 // NewStoreWithType 创建混合存储实例（指定数据库类型）
 func NewStoreWithType(dbType, dsn, redisAddr, redisPassword string, redisDB int) (*Store, error) {
-	var dbStore *postgres.Store
+	dbStore, err := openDatabase(dbType, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// 初始化 Redis
+	redisCache, err := redis.NewCache(redisAddr, redisPassword, redisDB)
+	if err != nil {
+		_ = dbStore.Close()
+		return nil, fmt.Errorf("%w: %v", ErrCacheUnavailable, err)
+	}
+
+	return &Store{
+		postgres:  dbStore,
+		redis:     redisCache,
+		ctx:       context.Background(),
+		negatives: newNegativeGuard(),
+	}, nil
+}
+
+// NewStandaloneStore 创建不依赖 Redis 的存储实例（单机部署）
+//
+// 持久数据仍写入数据库；黑名单、限流、会话等原本放在 Redis 的数据改由 local 在进程内保存，
+// 只适合单实例运行。
+func NewStandaloneStore(dbType, dsn string, local Local) (*Store, error) {
+	dbStore, err := openDatabase(dbType, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		postgres:  dbStore,
+		redis:     localCache{local: local},
+		ctx:       context.Background(),
+		negatives: newNegativeGuard(),
+	}, nil
+}
+
+// openDatabase 根据数据库类型创建存储
+func openDatabase(dbType, dsn string) (database, error) {
+	var sqlStore *postgres.Store
 	var err error
 
-	// 根据数据库类型创建存储
 	switch dbType {
 	case "mysql":
-		dbStore, err = postgres.NewMySQLStore(dsn)
+		sqlStore, err = postgres.NewMySQLStore(dsn)
 	case "postgres", "postgresql":
-		dbStore, err = postgres.NewStore(dsn)
+		sqlStore, err = postgres.NewStore(dsn)
+	case "sqlite", "sqlite3":
+		sqlStore, err = postgres.NewSQLiteStore(dsn)
+	case "mongodb", "mongo":
+		mongoStore, err := mongo.NewStore(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize database: %w", err)
+		}
+		return mongoDatabase{mongoStore}, nil
 	default:
-		return nil, fmt.Errorf("unsupported database type: %s (supported: mysql, postgres)", dbType)
+		return nil, fmt.Errorf("%w: %s (supported: mysql, postgres, sqlite, mongodb)", ErrUnsupportedDatabase, dbType)
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
+	return sqlDatabase{sqlStore}, nil
+}
 
-	// 初始化 Redis
-	redisCache, err := redis.NewCache(redisAddr, redisPassword, redisDB)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize redis: %w", err)
+// ========== Mailbox Repository ==========
+
+// CreateMailbox 创建新邮箱，地址已被占用时返回 storage.ErrAddressTaken
+func (s *Store) CreateMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	if err := s.postgres.CreateMailbox(ctx, mailbox); err != nil {
+		return err
 	}
 
-	return &Store{
-		postgres: dbStore,
-		redis:    redisCache,
-		ctx:      context.Background(),
-	}, nil
+	s.invalidateSavedMailbox(mailbox)
+	return s.redis.CacheMailbox(mailbox, 24*time.Hour)
 }
 
-// ========== Mailbox Repository ==========
-
 // SaveMailbox 保存邮箱信息
-func (s *Store) SaveMailbox(mailbox *domain.Mailbox) error {
+func (s *Store) SaveMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
 	// 保存到 PostgreSQL
-	if err := s.postgres.SaveMailbox(mailbox); err != nil {
+	if err := s.postgres.SaveMailbox(ctx, mailbox); err != nil {
 		return err
 	}
 
+	s.invalidateSavedMailbox(mailbox)
+
 	// 缓存到 Redis（24小时过期）
 	return s.redis.CacheMailbox(mailbox, 24*time.Hour)
 }
 
+// invalidateSavedMailbox 邮箱写入后清除按地址查询的负缓存（新邮箱立即可见）和所属用户的摘要
+func (s *Store) invalidateSavedMailbox(mailbox *domain.Mailbox) error {
+	owner := ""
+	if mailbox.UserID != nil {
+		owner = *mailbox.UserID
+	}
+	keys := mailboxKeys(mailbox.ID, owner)
+	return s.invalidate(append(keys, cachekey.NotFound(cachekey.MailboxAddress(mailbox.Address)))...)
+}
+
 // GetMailbox 根据 ID 获取邮箱
-func (s *Store) GetMailbox(id string) (*domain.Mailbox, error) {
+func (s *Store) GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error) {
 	// 先尝试从 Redis 获取
-	if mailbox, err := s.redis.GetCachedMailbox(id); err == nil {
+	if mailbox, err := s.redis.GetCachedMailbox(id); s.cacheHit(opGetMailbox, err) {
 		return mailbox, nil
 	}
 
-	// 从 PostgreSQL 获取
-	mailbox, err := s.postgres.GetMailbox(id)
+	// 从 PostgreSQL 获取（并发未命中合并为一次查询）
+	value, leader, err := s.coalesce(ctx, opGetMailbox, id, func(ctx context.Context) (interface{}, error) {
+		mailbox, err := s.postgres.GetMailbox(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		// 缓存到 Redis
+		s.redis.CacheMailbox(mailbox, 24*time.Hour)
+		return mailbox, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	// 缓存到 Redis
-	s.redis.CacheMailbox(mailbox, 24*time.Hour)
-	return mailbox, nil
+	return sharedMailbox(value.(*domain.Mailbox), leader), nil
 }
 
 // GetMailboxByAddress 根据完整地址获取邮箱
-func (s *Store) GetMailboxByAddress(address string) (*domain.Mailbox, error) {
-	// 从 PostgreSQL 获取（地址查询不缓存，因为变化频繁）
-	return s.postgres.GetMailboxByAddress(address)
+func (s *Store) GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error) {
+	// 地址查询不做正向缓存（变化频繁），只缓存“不存在”的结果
+	key := cachekey.MailboxAddress(address)
+	if s.isNegativeCached(opGetMailboxByAddress, key) {
+		return nil, postgres.ErrMailboxNotFound
+	}
+
+	generation := s.negatives.generation(key)
+	value, leader, err := s.coalesce(ctx, opGetMailboxByAddress, address, func(ctx context.Context) (interface{}, error) {
+		mailbox, err := s.postgres.GetMailboxByAddress(ctx, address)
+		if errors.Is(err, postgres.ErrMailboxNotFound) {
+			// 查库期间创建了该地址的邮箱时不写入（见 negativeGuard）
+			s.markNotFound(key, generation)
+		}
+		return mailbox, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sharedMailbox(value.(*domain.Mailbox), leader), nil
+}
+
+// GetMailboxesByAddresses 批量按地址查询邮箱（直接查库，与单个地址查询一样不做正向缓存）
+func (s *Store) GetMailboxesByAddresses(ctx context.Context, addresses []string) ([]domain.Mailbox, error) {
+	return s.postgres.GetMailboxesByAddresses(ctx, addresses)
 }
 
 // ListMailboxes 返回全部邮箱的快照
-func (s *Store) ListMailboxes() []domain.Mailbox {
+func (s *Store) ListMailboxes(ctx context.Context) []domain.Mailbox {
 	// 直接从 PostgreSQL 获取（列表查询不缓存）
-	return s.postgres.ListMailboxes()
+	return s.postgres.ListMailboxes(ctx)
 }
 
 // ListMailboxesByUserID 返回指定用户的全部邮箱
-func (s *Store) ListMailboxesByUserID(userID string) []domain.Mailbox {
+func (s *Store) ListMailboxesByUserID(ctx context.Context, userID string) []domain.Mailbox {
 	// 直接从 PostgreSQL 获取
-	return s.postgres.ListMailboxesByUserID(userID)
+	return s.postgres.ListMailboxesByUserID(ctx, userID)
+}
+
+// ListMailboxesByOrgID 根据组织ID获取邮箱列表
+func (s *Store) ListMailboxesByOrgID(ctx context.Context, orgID string) []domain.Mailbox {
+	return s.postgres.ListMailboxesByOrgID(ctx, orgID)
+}
+
+// ListMailboxSummariesByUserID 返回指定用户的邮箱摘要（按用户缓存 30 秒，收信、已读和删除时清除）
+func (s *Store) ListMailboxSummariesByUserID(ctx context.Context, userID string) ([]domain.MailboxSummary, error) {
+	if summaries, err := s.redis.GetCachedMailboxSummaries(userID); s.cacheHit(opListMailboxSummaries, err) {
+		return summaries, nil
+	}
+
+	summaries, err := s.postgres.ListMailboxSummariesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.redis.CacheMailboxSummaries(userID, summaries, mailboxSummariesCacheTTL)
+
+	return summaries, nil
+}
+
+// ListMailboxSummariesByOrgID 返回指定组织的邮箱摘要（不缓存：组织成员变化时无法按用户清除）
+func (s *Store) ListMailboxSummariesByOrgID(ctx context.Context, orgID string) ([]domain.MailboxSummary, error) {
+	return s.postgres.ListMailboxSummariesByOrgID(ctx, orgID)
+}
+
+// mailboxSummariesCacheTTL 邮箱摘要缓存有效期
+const mailboxSummariesCacheTTL = 30 * time.Second
+
+// mailboxOwner 邮箱所属用户 ID（游客邮箱或邮箱不存在时为空）
+func (s *Store) mailboxOwner(ctx context.Context, mailboxID string) string {
+	mailbox, err := s.GetMailbox(ctx, mailboxID)
+	if err != nil || mailbox.UserID == nil {
+		return ""
+	}
+	return *mailbox.UserID
 }
 
 // DeleteMailbox 删除指定邮箱
-func (s *Store) DeleteMailbox(id string) error {
+func (s *Store) DeleteMailbox(ctx context.Context, id string) error {
+	// 删除前记下所属用户，用于清除摘要缓存
+	owner := s.mailboxOwner(ctx, id)
+
 	// 从 PostgreSQL 删除
-	if err := s.postgres.DeleteMailbox(id); err != nil {
+	if err := s.postgres.DeleteMailbox(ctx, id); err != nil {
 		return err
 	}
+	s.invalidate(mailboxKeys(id, owner)...)
 
-	// 从 Redis 删除缓存
-	s.redis.DeleteCachedMailbox(id)
-	s.redis.DeleteCachedMessageList(id)
+	// 从 Redis 删除缓存（失败由邮箱删除流程的对账任务重试）
+	s.EvictMailboxCache(id)
 
 	return nil
 }
 
+// ListExpiredMailboxes 列出在指定时间已过期的邮箱
+func (s *Store) ListExpiredMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error) {
+	return s.postgres.ListExpiredMailboxes(ctx, now)
+}
+
+// TouchMailbox 记录访问时间并清除缓存的邮箱（有效期可能被恢复）
+func (s *Store) TouchMailbox(ctx context.Context, mailboxID string, at time.Time) error {
+	if err := s.postgres.TouchMailbox(ctx, mailboxID, at); err != nil {
+		return err
+	}
+	s.invalidateMailbox(ctx, mailboxID)
+	return nil
+}
+
+// ListIdleMailboxes 列出闲置的用户邮箱
+func (s *Store) ListIdleMailboxes(ctx context.Context, before, now time.Time) ([]domain.Mailbox, error) {
+	return s.postgres.ListIdleMailboxes(ctx, before, now)
+}
+
+// ListPublicMailboxes 列出公开收件箱
+func (s *Store) ListPublicMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error) {
+	return s.postgres.ListPublicMailboxes(ctx, now)
+}
+
+// MarkMailboxIdle 标记邮箱闲置并清除缓存的邮箱
+func (s *Store) MarkMailboxIdle(ctx context.Context, mailboxID string, at time.Time, shortenTo *time.Time) error {
+	if err := s.postgres.MarkMailboxIdle(ctx, mailboxID, at, shortenTo); err != nil {
+		return err
+	}
+	s.invalidateMailbox(ctx, mailboxID)
+	return nil
+}
+
+// ExtendMailbox 设置新的过期时间并清除缓存的邮箱
+func (s *Store) ExtendMailbox(ctx context.Context, mailboxID string, expiresAt time.Time) error {
+	if err := s.postgres.ExtendMailbox(ctx, mailboxID, expiresAt); err != nil {
+		return err
+	}
+	s.invalidateMailbox(ctx, mailboxID)
+	return nil
+}
+
 // DeleteExpiredMailboxes 删除所有过期的邮箱，返回删除数量
-func (s *Store) DeleteExpiredMailboxes() (int, error) {
+func (s *Store) DeleteExpiredMailboxes(ctx context.Context) (int, error) {
 	// 直接从 PostgreSQL 删除
-	return s.postgres.DeleteExpiredMailboxes()
+	return s.postgres.DeleteExpiredMailboxes(ctx)
 }
 
 // ========== Message Repository ==========
 
 // SaveMessage 保存邮件信息
-func (s *Store) SaveMessage(message *domain.Message) error {
+func (s *Store) SaveMessage(ctx context.Context, message *domain.Message) error {
 	// 保存到 PostgreSQL
-	if err := s.postgres.SaveMessage(message); err != nil {
+	if err := s.postgres.SaveMessage(ctx, message); err != nil {
 		return err
 	}
 
@@ -138,24 +325,45 @@ func (s *Store) SaveMessage(message *domain.Message) error {
 		fmt.Printf("Warning: failed to cache message: %v\n", err)
 	}
 
-	// 删除邮件列表缓存（因为列表已变化）
-	s.redis.DeleteCachedMessageList(message.MailboxID)
+	// 删除邮件列表和邮箱摘要缓存（因为列表已变化）
+	s.invalidateMessages(ctx, message.MailboxID)
 
-	// 发布新邮件通知
-	s.redis.PublishNewMail(message.MailboxID, message)
+	// 新邮件事件由 MessageService 在内容落盘后发布，避免订阅方先于邮件可见收到通知
+	return nil
+}
 
+// SaveMessages 批量保存邮件，之后逐个更新缓存
+func (s *Store) SaveMessages(ctx context.Context, messages []*domain.Message) error {
+	if err := s.postgres.SaveMessages(ctx, messages); err != nil {
+		return err
+	}
+	s.cacheSavedMessages(ctx, messages)
 	return nil
 }
 
+// cacheSavedMessages 缓存批量写入的邮件，并失效各邮箱的列表缓存（每个邮箱一次）
+func (s *Store) cacheSavedMessages(ctx context.Context, messages []*domain.Message) {
+	cleared := make(map[string]bool)
+	for _, message := range messages {
+		if err := s.redis.CacheMessage(message, 24*time.Hour); err != nil {
+			fmt.Printf("Warning: failed to cache message: %v\n", err)
+		}
+		if !cleared[message.MailboxID] {
+			cleared[message.MailboxID] = true
+			s.invalidateMessages(ctx, message.MailboxID)
+		}
+	}
+}
+
 // ListMessages 返回某个邮箱下的全部邮件
-func (s *Store) ListMessages(mailboxID string) ([]domain.Message, error) {
+func (s *Store) ListMessages(ctx context.Context, mailboxID string) ([]domain.Message, error) {
 	// 先尝试从 Redis 获取
-	if messages, err := s.redis.GetCachedMessageList(mailboxID); err == nil {
+	if messages, err := s.redis.GetCachedMessageList(mailboxID); s.cacheHit(opListMessages, err) {
 		return messages, nil
 	}
 
 	// 从 PostgreSQL 获取
-	messages, err := s.postgres.ListMessages(mailboxID)
+	messages, err := s.postgres.ListMessages(ctx, mailboxID)
 	if err != nil {
 		return nil, err
 	}
@@ -166,85 +374,155 @@ func (s *Store) ListMessages(mailboxID string) ([]domain.Message, error) {
 	return messages, nil
 }
 
+// ListMessagesPage 分页列出邮件：邮件列表已缓存时在内存中分页，否则下推到 PostgreSQL（不写入缓存）
+func (s *Store) ListMessagesPage(ctx context.Context, query domain.MessageListQuery) (*domain.MessagePage, error) {
+	if messages, err := s.redis.GetCachedMessageList(query.MailboxID); s.cacheHit(opListMessages, err) {
+		return domain.PageMessages(messages, query), nil
+	}
+	return s.postgres.ListMessagesPage(ctx, query)
+}
+
 // GetMessage 获取单封邮件
-func (s *Store) GetMessage(mailboxID, messageID string) (*domain.Message, error) {
+func (s *Store) GetMessage(ctx context.Context, mailboxID, messageID string) (*domain.Message, error) {
 	// 先尝试从 Redis 获取
-	if message, err := s.redis.GetCachedMessage(mailboxID, messageID); err == nil {
+	if message, err := s.redis.GetCachedMessage(mailboxID, messageID); s.cacheHit(opGetMessage, err) {
 		return message, nil
 	}
 
-	// 从 PostgreSQL 获取
-	message, err := s.postgres.GetMessage(mailboxID, messageID)
+	// 从 PostgreSQL 获取（并发未命中合并为一次查询）
+	value, leader, err := s.coalesce(ctx, opGetMessage, mailboxID+":"+messageID, func(ctx context.Context) (interface{}, error) {
+		message, err := s.postgres.GetMessage(ctx, mailboxID, messageID)
+		if err != nil {
+			return nil, err
+		}
+
+		// 缓存到 Redis
+		s.redis.CacheMessage(message, 24*time.Hour)
+		return message, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// 缓存到 Redis
-	s.redis.CacheMessage(message, 24*time.Hour)
-
+	message := value.(*domain.Message)
+	if !leader {
+		copied := *message
+		message = &copied
+	}
 	return message, nil
 }
 
+// sharedMailbox 非 leader 调用方返回邮箱副本
+func sharedMailbox(mailbox *domain.Mailbox, leader bool) *domain.Mailbox {
+	if leader {
+		return mailbox
+	}
+	copied := *mailbox
+	return &copied
+}
+
 // MarkMessageRead 将邮件标记为已读
-func (s *Store) MarkMessageRead(mailboxID, messageID string) error {
+func (s *Store) MarkMessageRead(ctx context.Context, mailboxID, messageID string) error {
 	// 更新 PostgreSQL
-	if err := s.postgres.MarkMessageRead(mailboxID, messageID); err != nil {
+	if err := s.postgres.MarkMessageRead(ctx, mailboxID, messageID); err != nil {
 		return err
 	}
 
 	// 删除相关缓存
-	s.redis.Delete(fmt.Sprintf("message:%s:%s", mailboxID, messageID))
-	s.redis.DeleteCachedMessageList(mailboxID)
+	s.invalidateMessages(ctx, mailboxID, messageID)
+
+	return nil
+}
+
+// MarkMessageUnread 将邮件标记为未读
+func (s *Store) MarkMessageUnread(ctx context.Context, mailboxID, messageID string) error {
+	if err := s.postgres.MarkMessageUnread(ctx, mailboxID, messageID); err != nil {
+		return err
+	}
+
+	s.invalidateMessages(ctx, mailboxID, messageID)
 
 	return nil
 }
 
 // DeleteMessage 删除单封邮件
-func (s *Store) DeleteMessage(mailboxID, messageID string) error {
+func (s *Store) DeleteMessage(ctx context.Context, mailboxID, messageID string) error {
 	// 从 PostgreSQL 删除
-	if err := s.postgres.DeleteMessage(mailboxID, messageID); err != nil {
+	if err := s.postgres.DeleteMessage(ctx, mailboxID, messageID); err != nil {
 		return err
 	}
 
 	// 删除 Redis 缓存
-	s.redis.Delete(fmt.Sprintf("message:%s:%s", mailboxID, messageID))
-	s.redis.DeleteCachedMessageList(mailboxID)
+	s.invalidateMessages(ctx, mailboxID, messageID)
 
 	return nil
 }
 
 // DeleteAllMessages 删除邮箱的所有邮件
-func (s *Store) DeleteAllMessages(mailboxID string) (int, error) {
+func (s *Store) DeleteAllMessages(ctx context.Context, mailboxID string) (int, error) {
 	// 从 PostgreSQL 删除
-	count, err := s.postgres.DeleteAllMessages(mailboxID)
+	count, err := s.postgres.DeleteAllMessages(ctx, mailboxID)
 	if err != nil {
 		return 0, err
 	}
 
 	// 删除 Redis 缓存
-	s.redis.DeleteCachedMessageList(mailboxID)
+	s.invalidateMessages(ctx, mailboxID)
 
 	return count, nil
 }
 
+// SetMessageExpiry 设置单封邮件的删除时间
+func (s *Store) SetMessageExpiry(ctx context.Context, mailboxID, messageID string, expiresAt *time.Time) error {
+	if err := s.postgres.SetMessageExpiry(ctx, mailboxID, messageID, expiresAt); err != nil {
+		return err
+	}
+
+	s.invalidateMessages(ctx, mailboxID, messageID)
+
+	return nil
+}
+
+// SetMessageQuarantined 将邮件移入或移出隔离区，并失效邮件缓存和邮箱摘要
+func (s *Store) SetMessageQuarantined(ctx context.Context, mailboxID, messageID string, quarantined bool) error {
+	if err := s.postgres.SetMessageQuarantined(ctx, mailboxID, messageID, quarantined); err != nil {
+		return err
+	}
+
+	s.invalidateMessages(ctx, mailboxID, messageID) // 摘要不统计隔离区邮件
+
+	return nil
+}
+
+// DeleteExpiredMessages 删除按规则到期的邮件，并失效受影响邮箱的缓存
+func (s *Store) DeleteExpiredMessages(ctx context.Context, now time.Time) ([]domain.ExpiredMessages, error) {
+	// 部分邮箱失败时，已删除的邮箱同样需要失效缓存
+	expired, err := s.postgres.DeleteExpiredMessages(ctx, now)
+	for _, batch := range expired {
+		s.invalidateMessages(ctx, batch.MailboxID, batch.MessageIDs...)
+		s.invalidate(cachekey.Mailbox(batch.MailboxID)) // 统计已变化
+	}
+	return expired, err
+}
+
 // GetAttachment 获取邮件附件
-func (s *Store) GetAttachment(mailboxID, messageID, attachmentID string) (*domain.Attachment, error) {
+func (s *Store) GetAttachment(ctx context.Context, mailboxID, messageID, attachmentID string) (*domain.Attachment, error) {
 	// 附件直接从 PostgreSQL 获取（不缓存，因为文件较大）
-	return s.postgres.GetAttachment(mailboxID, messageID, attachmentID)
+	return s.postgres.GetAttachment(ctx, mailboxID, messageID, attachmentID)
 }
 
 // SearchMessages 搜索邮件
-func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error) {
+func (s *Store) SearchMessages(ctx context.Context, criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error) {
 	// 直接从 PostgreSQL 搜索（搜索结果不缓存）
-	return s.postgres.SearchMessages(criteria)
+	return s.postgres.SearchMessages(ctx, criteria)
 }
 
 // ========== User Repository ==========
 
 // CreateUser 创建新用户
-func (s *Store) CreateUser(user *domain.User) error {
+func (s *Store) CreateUser(ctx context.Context, user *domain.User) error {
 	// 保存到 PostgreSQL
-	if err := s.postgres.CreateUser(user); err != nil {
+	if err := s.postgres.CreateUser(ctx, user); err != nil {
 		return err
 	}
 
@@ -255,9 +533,9 @@ func (s *Store) CreateUser(user *domain.User) error {
 
 // GetUserByID 根据ID获取用户
 // 注意：不使用Redis缓存，因为PasswordHash字段有json:"-"标签，缓存后会丢失
-func (s *Store) GetUserByID(id string) (*domain.User, error) {
+func (s *Store) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
 	// 直接从 PostgreSQL 获取，不经过Redis缓存
-	user, err := s.postgres.GetUserByID(id)
+	user, err := s.postgres.GetUserByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -267,9 +545,9 @@ func (s *Store) GetUserByID(id string) (*domain.User, error) {
 
 // GetUserByEmail 根据邮箱获取用户
 // 注意：不使用Redis缓存，因为PasswordHash字段有json:"-"标签，缓存后会丢失
-func (s *Store) GetUserByEmail(email string) (*domain.User, error) {
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
 	// 直接从 PostgreSQL 获取，不经过Redis缓存
-	user, err := s.postgres.GetUserByEmail(email)
+	user, err := s.postgres.GetUserByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
@@ -278,19 +556,19 @@ func (s *Store) GetUserByEmail(email string) (*domain.User, error) {
 }
 
 // GetUserByUsername 根据用户名获取用户
-func (s *Store) GetUserByUsername(username string) (*domain.User, error) {
-	return s.postgres.GetUserByUsername(username)
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	return s.postgres.GetUserByUsername(ctx, username)
 }
 
 // GetUserByAPIKey 根据API Key获取用户
-func (s *Store) GetUserByAPIKey(apiKey string) (*domain.User, error) {
+func (s *Store) GetUserByAPIKey(ctx context.Context, apiKey string) (*domain.User, error) {
 	// 先尝试从 Redis 获取缓存的用户ID
-	if userID, err := s.redis.GetCachedAPIKeyUser(apiKey); err == nil {
-		return s.GetUserByID(userID)
+	if userID, err := s.redis.GetCachedAPIKeyUser(apiKey); s.cacheHit(opGetUserByAPIKey, err) {
+		return s.GetUserByID(ctx, userID)
 	}
 
 	// 从 PostgreSQL 获取
-	user, err := s.postgres.GetUserByAPIKey(apiKey)
+	user, err := s.postgres.GetUserByAPIKey(ctx, apiKey)
 	if err != nil {
 		return nil, err
 	}
@@ -305,28 +583,32 @@ func (s *Store) GetUserByAPIKey(apiKey string) (*domain.User, error) {
 // ========== API Key Repository ==========
 
 // SaveAPIKey 保存API Key
-func (s *Store) SaveAPIKey(apiKey *domain.APIKey) error {
+func (s *Store) SaveAPIKey(ctx context.Context, apiKey *domain.APIKey) error {
 	// 保存到 PostgreSQL
-	if err := s.postgres.SaveAPIKey(apiKey); err != nil {
+	if err := s.postgres.SaveAPIKey(ctx, apiKey); err != nil {
 		return err
 	}
 
-	// 缓存到 Redis（24小时过期）
+	// 缓存到 Redis（24小时过期）；停用的 Key 只失效映射，不能再通过缓存认证
 	s.redis.CacheAPIKey(apiKey, 24*time.Hour)
+	if !apiKey.IsActive {
+		s.invalidate(cachekey.APIKeyUser(apiKey.Key))
+		return nil
+	}
 	s.redis.CacheAPIKeyUser(apiKey.Key, apiKey.UserID, 24*time.Hour)
 
 	return nil
 }
 
 // GetAPIKey 根据ID获取API Key
-func (s *Store) GetAPIKey(id string) (*domain.APIKey, error) {
+func (s *Store) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
 	// 先尝试从 Redis 获取
-	if apiKey, err := s.redis.GetCachedAPIKey(id); err == nil {
+	if apiKey, err := s.redis.GetCachedAPIKey(id); s.cacheHit(opGetAPIKey, err) {
 		return apiKey, nil
 	}
 
 	// 从 PostgreSQL 获取
-	apiKey, err := s.postgres.GetAPIKey(id)
+	apiKey, err := s.postgres.GetAPIKey(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -338,72 +620,79 @@ func (s *Store) GetAPIKey(id string) (*domain.APIKey, error) {
 }
 
 // GetAPIKeyByKey 根据Key字符串获取API Key
-func (s *Store) GetAPIKeyByKey(key string) (*domain.APIKey, error) {
+func (s *Store) GetAPIKeyByKey(ctx context.Context, key string) (*domain.APIKey, error) {
 	// API Key查询直接从 PostgreSQL 获取（安全考虑）
-	apiKey, err := s.postgres.GetAPIKeyByKey(key)
+	apiKey, err := s.postgres.GetAPIKeyByKey(ctx, key)
 	if err != nil {
 		return nil, err
 	}
 
 	// 更新最后使用时间
-	s.postgres.UpdateAPIKeyLastUsed(apiKey.ID)
+	s.postgres.UpdateAPIKeyLastUsed(ctx, apiKey.ID)
 
 	return apiKey, nil
 }
 
 // ListAPIKeysByUserID 列出用户的所有API Key
-func (s *Store) ListAPIKeysByUserID(userID string) ([]*domain.APIKey, error) {
+func (s *Store) ListAPIKeysByUserID(ctx context.Context, userID string) ([]*domain.APIKey, error) {
 	// API Key列表直接从 PostgreSQL 获取（不缓存）
-	return s.postgres.ListAPIKeysByUserID(userID)
+	return s.postgres.ListAPIKeysByUserID(ctx, userID)
 }
 
 // DeleteAPIKey 删除API Key
-func (s *Store) DeleteAPIKey(id string) error {
+func (s *Store) DeleteAPIKey(ctx context.Context, id string) error {
+	// 删除前记下 Key 字符串，同时失效 Key 到用户的映射，已删除的 Key 不能再认证
+	keys := []cachekey.Key{cachekey.APIKey(id)}
+	if apiKey, err := s.postgres.GetAPIKey(ctx, id); err == nil {
+		keys = append(keys, cachekey.APIKeyUser(apiKey.Key))
+	}
+
 	// 从 PostgreSQL 删除
-	if err := s.postgres.DeleteAPIKey(id); err != nil {
+	if err := s.postgres.DeleteAPIKey(ctx, id); err != nil {
 		return err
 	}
 
 	// 删除 Redis 缓存
-	s.redis.Delete(fmt.Sprintf("apikey:%s", id))
+	s.invalidate(keys...)
 
 	return nil
 }
 
 // UpdateAPIKeyLastUsed 更新API Key最后使用时间
-func (s *Store) UpdateAPIKeyLastUsed(id string) error {
+func (s *Store) UpdateAPIKeyLastUsed(ctx context.Context, id string) error {
 	// 更新 PostgreSQL
-	if err := s.postgres.UpdateAPIKeyLastUsed(id); err != nil {
+	if err := s.postgres.UpdateAPIKeyLastUsed(ctx, id); err != nil {
 		return err
 	}
 
 	// 删除缓存（强制重新加载）
-	s.redis.Delete(fmt.Sprintf("apikey:%s", id))
+	s.invalidate(cachekey.APIKey(id))
 
 	return nil
 }
 
 // UpdateUser 更新用户信息
-func (s *Store) UpdateUser(user *domain.User) error {
+func (s *Store) UpdateUser(ctx context.Context, user *domain.User) error {
 	// 更新 PostgreSQL
-	if err := s.postgres.UpdateUser(user); err != nil {
+	if err := s.postgres.UpdateUser(ctx, user); err != nil {
 		return err
 	}
 
-	// 不缓存用户，因为PasswordHash字段无法正确序列化
+	// 按 API Key 查询时写入过用户缓存，更新后一并失效
+	s.invalidateUser(user.ID)
 
 	return nil
 }
 
 // UpdateLastLogin 更新用户最后登录时间
-func (s *Store) UpdateLastLogin(userID string) error {
+func (s *Store) UpdateLastLogin(ctx context.Context, userID string) error {
 	// 更新 PostgreSQL
-	if err := s.postgres.UpdateLastLogin(userID); err != nil {
+	if err := s.postgres.UpdateLastLogin(ctx, userID); err != nil {
 		return err
 	}
 
 	// 删除用户缓存（强制重新加载）
-	s.redis.Delete(fmt.Sprintf("user:%s", userID))
+	s.invalidateUser(userID)
 
 	return nil
 }
@@ -411,39 +700,58 @@ func (s *Store) UpdateLastLogin(userID string) error {
 // ========== Admin Repository ==========
 
 // ListUsers 列出用户（支持分页和过滤）
-func (s *Store) ListUsers(page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) {
+func (s *Store) ListUsers(ctx context.Context, page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) {
 	// 管理功能直接从 PostgreSQL 获取（不缓存）
-	return s.postgres.ListUsers(page, pageSize, search, role, tier, isActive)
+	return s.postgres.ListUsers(ctx, page, pageSize, search, role, tier, isActive)
 }
 
 // DeleteUser 删除用户
-func (s *Store) DeleteUser(userID string) error {
+func (s *Store) DeleteUser(ctx context.Context, userID string) error {
+	// 删除前记下用户的 API Key，失效它们到用户的映射
+	var apiKeys []string
+	if keys, err := s.postgres.ListAPIKeysByUserID(ctx, userID); err == nil {
+		for _, apiKey := range keys {
+			apiKeys = append(apiKeys, apiKey.Key)
+		}
+	}
+
 	// 从 PostgreSQL 删除
-	if err := s.postgres.DeleteUser(userID); err != nil {
+	if err := s.postgres.DeleteUser(ctx, userID); err != nil {
 		return err
 	}
 
 	// 删除 Redis 缓存
-	s.redis.Delete(fmt.Sprintf("user:%s", userID))
+	s.invalidateUser(userID, apiKeys...)
+	s.invalidate(cachekey.MailboxSummaries(userID))
 
 	return nil
 }
 
 // DeleteMailboxesByUserID 删除用户的所有邮箱
-func (s *Store) DeleteMailboxesByUserID(userID string) error {
+func (s *Store) DeleteMailboxesByUserID(ctx context.Context, userID string) error {
+	// 删除前记下邮箱 ID，失效每个邮箱的缓存
+	mailboxes := s.postgres.ListMailboxesByUserID(ctx, userID)
+
 	// 从 PostgreSQL 删除
-	return s.postgres.DeleteMailboxesByUserID(userID)
+	if err := s.postgres.DeleteMailboxesByUserID(ctx, userID); err != nil {
+		return err
+	}
+	s.invalidate(cachekey.MailboxSummaries(userID))
+	for _, mailbox := range mailboxes {
+		s.EvictMailboxCache(mailbox.ID)
+	}
+	return nil
 }
 
 // GetSystemStatistics 获取系统统计信息
-func (s *Store) GetSystemStatistics() (*domain.SystemStatistics, error) {
+func (s *Store) GetSystemStatistics(ctx context.Context) (*domain.SystemStatistics, error) {
 	// 先尝试从 Redis 获取
-	if stats, err := s.redis.GetCachedStatistics(); err == nil {
+	if stats, err := s.redis.GetCachedStatistics(); s.cacheHit(opGetSystemStatistics, err) {
 		return stats, nil
 	}
 
 	// 从 PostgreSQL 获取
-	stats, err := s.postgres.GetSystemStatistics()
+	stats, err := s.postgres.GetSystemStatistics(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -455,158 +763,196 @@ func (s *Store) GetSystemStatistics() (*domain.SystemStatistics, error) {
 }
 
 // GetDomainStatistics 获取域名统计信息
-func (s *Store) GetDomainStatistics(domain string) (mailboxCount, messageCount int, err error) {
+func (s *Store) GetDomainStatistics(ctx context.Context, domain string) (mailboxCount, messageCount int, err error) {
 	// 域名统计直接从 PostgreSQL 获取
-	return s.postgres.GetDomainStatistics(domain)
+	return s.postgres.GetDomainStatistics(ctx, domain)
+}
+
+// messageStatsCacheTTL 邮件统计缓存有效期
+const messageStatsCacheTTL = 5 * time.Minute
+
+// GetMessageStats 获取邮件收件统计（按邮箱集合与时间窗口缓存 5 分钟）
+func (s *Store) GetMessageStats(ctx context.Context, query domain.MessageStatsQuery) (*domain.MessageStats, error) {
+	key := messageStatsKey(query)
+	if stats, err := s.redis.GetCachedMessageStats(key); s.cacheHit(opGetMessageStats, err) {
+		return stats, nil
+	}
+
+	stats, err := s.postgres.GetMessageStats(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.redis.CacheMessageStats(key, stats, messageStatsCacheTTL)
+
+	return stats, nil
+}
+
+// messageStatsKey 统计缓存键：邮箱 ID 排序后取摘要，加上窗口起止和排行数量
+func messageStatsKey(query domain.MessageStatsQuery) string {
+	ids := append([]string(nil), query.MailboxIDs...)
+	sort.Strings(ids)
+	sum := sha256.Sum256([]byte(strings.Join(ids, ",")))
+	return fmt.Sprintf("%x:%d:%d:%d", sum[:8], query.Since.Unix(), query.Until.Unix(), query.TopSenders)
 }
 
 // ========== Alias Repository ==========
 
 // SaveAlias 保存邮箱别名
-func (s *Store) SaveAlias(alias *domain.MailboxAlias) error {
+func (s *Store) SaveAlias(ctx context.Context, alias *domain.MailboxAlias) error {
 	// 保存到 PostgreSQL
-	return s.postgres.SaveAlias(alias)
+	return s.postgres.SaveAlias(ctx, alias)
 }
 
 // GetAlias 根据ID获取别名
-func (s *Store) GetAlias(aliasID string) (*domain.MailboxAlias, error) {
+func (s *Store) GetAlias(ctx context.Context, aliasID string) (*domain.MailboxAlias, error) {
 	// 别名直接从 PostgreSQL 获取（不缓存）
-	return s.postgres.GetAlias(aliasID)
+	return s.postgres.GetAlias(ctx, aliasID)
 }
 
 // GetAliasByAddress 根据地址获取别名
-func (s *Store) GetAliasByAddress(address string) (*domain.MailboxAlias, error) {
+func (s *Store) GetAliasByAddress(ctx context.Context, address string) (*domain.MailboxAlias, error) {
 	// 别名地址查询直接从 PostgreSQL 获取
-	return s.postgres.GetAliasByAddress(address)
+	return s.postgres.GetAliasByAddress(ctx, address)
 }
 
 // ListAliasesByMailboxID 列出指定邮箱的所有别名
-func (s *Store) ListAliasesByMailboxID(mailboxID string) ([]*domain.MailboxAlias, error) {
+func (s *Store) ListAliasesByMailboxID(ctx context.Context, mailboxID string) ([]*domain.MailboxAlias, error) {
 	// 别名列表直接从 PostgreSQL 获取
-	return s.postgres.ListAliasesByMailboxID(mailboxID)
+	return s.postgres.ListAliasesByMailboxID(ctx, mailboxID)
 }
 
 // DeleteAlias 删除别名
-func (s *Store) DeleteAlias(aliasID string) error {
+func (s *Store) DeleteAlias(ctx context.Context, aliasID string) error {
 	// 从 PostgreSQL 删除
-	return s.postgres.DeleteAlias(aliasID)
+	return s.postgres.DeleteAlias(ctx, aliasID)
 }
 
 // ========== User Domain Repository ==========
 
 // SaveUserDomain 保存用户域名
-func (s *Store) SaveUserDomain(userDomain *domain.UserDomain) error {
+func (s *Store) SaveUserDomain(ctx context.Context, userDomain *domain.UserDomain) error {
 	// 保存到 PostgreSQL
-	return s.postgres.SaveUserDomain(userDomain)
+	return s.postgres.SaveUserDomain(ctx, userDomain)
 }
 
 // GetUserDomain 根据ID获取用户域名
-func (s *Store) GetUserDomain(domainID string) (*domain.UserDomain, error) {
+func (s *Store) GetUserDomain(ctx context.Context, domainID string) (*domain.UserDomain, error) {
 	// 用户域名直接从 PostgreSQL 获取
-	return s.postgres.GetUserDomain(domainID)
+	return s.postgres.GetUserDomain(ctx, domainID)
 }
 
 // GetUserDomainByDomain 根据域名获取用户域名
-func (s *Store) GetUserDomainByDomain(domain string) (*domain.UserDomain, error) {
+func (s *Store) GetUserDomainByDomain(ctx context.Context, domain string) (*domain.UserDomain, error) {
 	// 域名查询直接从 PostgreSQL 获取
-	return s.postgres.GetUserDomainByDomain(domain)
+	return s.postgres.GetUserDomainByDomain(ctx, domain)
 }
 
 // ListUserDomainsByUserID 列出用户的所有域名
-func (s *Store) ListUserDomainsByUserID(userID string) ([]*domain.UserDomain, error) {
+func (s *Store) ListUserDomainsByUserID(ctx context.Context, userID string) ([]*domain.UserDomain, error) {
 	// 用户域名列表直接从 PostgreSQL 获取
-	return s.postgres.ListUserDomainsByUserID(userID)
+	return s.postgres.ListUserDomainsByUserID(ctx, userID)
+}
+
+// ListUserDomainsByOrgID 获取组织的所有域名
+func (s *Store) ListUserDomainsByOrgID(ctx context.Context, orgID string) ([]*domain.UserDomain, error) {
+	return s.postgres.ListUserDomainsByOrgID(ctx, orgID)
 }
 
 // UpdateUserDomain 更新用户域名
-func (s *Store) UpdateUserDomain(userDomain *domain.UserDomain) error {
+func (s *Store) UpdateUserDomain(ctx context.Context, userDomain *domain.UserDomain) error {
 	// 更新 PostgreSQL
-	return s.postgres.UpdateUserDomain(userDomain)
+	return s.postgres.UpdateUserDomain(ctx, userDomain)
 }
 
 // ListAllUserDomains 列出所有用户域名
-func (s *Store) ListAllUserDomains() ([]*domain.UserDomain, error) {
-	return s.postgres.ListAllUserDomains()
+func (s *Store) ListAllUserDomains(ctx context.Context) ([]*domain.UserDomain, error) {
+	return s.postgres.ListAllUserDomains(ctx)
 }
 
 // DeleteUserDomain 删除用户域名
-func (s *Store) DeleteUserDomain(domainID string) error {
+func (s *Store) DeleteUserDomain(ctx context.Context, domainID string) error {
 	// 从 PostgreSQL 删除
-	return s.postgres.DeleteUserDomain(domainID)
+	return s.postgres.DeleteUserDomain(ctx, domainID)
 }
 
 // IncrementMailboxCount 增加域名邮箱计数
-func (s *Store) IncrementMailboxCount(domain string) error {
+func (s *Store) IncrementMailboxCount(ctx context.Context, domain string) error {
 	// 更新 PostgreSQL
-	return s.postgres.IncrementMailboxCount(domain)
+	return s.postgres.IncrementMailboxCount(ctx, domain)
 }
 
 // DecrementMailboxCount 减少域名邮箱计数
-func (s *Store) DecrementMailboxCount(domain string) error {
+func (s *Store) DecrementMailboxCount(ctx context.Context, domain string) error {
 	// 更新 PostgreSQL
-	return s.postgres.DecrementMailboxCount(domain)
+	return s.postgres.DecrementMailboxCount(ctx, domain)
 }
 
 // ========== JWT 黑名单 ==========
 
 // AddToBlacklist 将 JWT 添加到黑名单
-func (s *Store) AddToBlacklist(jti string, ttl time.Duration) error {
+func (s *Store) AddToBlacklist(ctx context.Context, jti string, ttl time.Duration) error {
 	// 只使用 Redis 存储黑名单
-	return s.redis.AddToBlacklist(jti, ttl)
+	return s.redis.AddToBlacklist(ctx, jti, ttl)
 }
 
 // IsBlacklisted 检查 JWT 是否在黑名单中
-func (s *Store) IsBlacklisted(jti string) (bool, error) {
+func (s *Store) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
 	// 只从 Redis 检查黑名单
-	return s.redis.IsBlacklisted(jti)
+	return s.redis.IsBlacklisted(ctx, jti)
 }
 
 // ========== 限流 ==========
 
 // IncrementRateLimit 增加限流计数
-func (s *Store) IncrementRateLimit(key string, window time.Duration) (int64, error) {
+func (s *Store) IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error) {
 	// 只使用 Redis 进行限流
-	return s.redis.IncrementRateLimit(key, window)
+	return s.redis.IncrementRateLimit(ctx, key, window)
 }
 
 // GetRateLimit 获取限流计数
-func (s *Store) GetRateLimit(key string) (int64, error) {
+func (s *Store) GetRateLimit(ctx context.Context, key string) (int64, error) {
 	// 只从 Redis 获取限流计数
-	return s.redis.GetRateLimit(key)
+	return s.redis.GetRateLimit(ctx, key)
+}
+
+// ResetRateLimit 清零限流计数
+func (s *Store) ResetRateLimit(ctx context.Context, key string) error {
+	return s.redis.ResetRateLimit(ctx, key)
 }
 
 // ========== 会话管理 ==========
 
 // CacheSession 缓存用户会话
-func (s *Store) CacheSession(sessionID string, userID string, ttl time.Duration) error {
+func (s *Store) CacheSession(ctx context.Context, sessionID string, userID string, ttl time.Duration) error {
 	// 只使用 Redis 存储会话
-	return s.redis.CacheSession(sessionID, userID, ttl)
+	return s.redis.CacheSession(ctx, sessionID, userID, ttl)
 }
 
 // GetCachedSession 获取缓存的会话
-func (s *Store) GetCachedSession(sessionID string) (string, error) {
+func (s *Store) GetCachedSession(ctx context.Context, sessionID string) (string, error) {
 	// 只从 Redis 获取会话
-	return s.redis.GetCachedSession(sessionID)
+	return s.redis.GetCachedSession(ctx, sessionID)
 }
 
 // DeleteCachedSession 删除缓存的会话
-func (s *Store) DeleteCachedSession(sessionID string) error {
+func (s *Store) DeleteCachedSession(ctx context.Context, sessionID string) error {
 	// 只从 Redis 删除会话
-	return s.redis.DeleteCachedSession(sessionID)
+	return s.redis.DeleteCachedSession(ctx, sessionID)
 }
 
 // ========== 发布订阅 ==========
 
 // PublishNewMail 发布新邮件通知
-func (s *Store) PublishNewMail(mailboxID string, message *domain.Message) error {
+func (s *Store) PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error {
 	// 使用 Redis 发布订阅
-	return s.redis.PublishNewMail(mailboxID, message)
+	return s.redis.PublishNewMail(ctx, mailboxID, message)
 }
 
-// SubscribeNewMail 订阅新邮件通知
-func (s *Store) SubscribeNewMail(mailboxID string) interface{} {
+// SubscribeNewMail 订阅所有邮箱的新邮件通知（单机模式返回 storage.ErrPubSubUnavailable）
+func (s *Store) SubscribeNewMail(ctx context.Context) (<-chan *domain.Message, error) {
 	// 使用 Redis 发布订阅
-	return s.redis.SubscribeNewMail(mailboxID)
+	return s.redis.SubscribeNewMail(ctx)
 }
 
 // ========== 工具方法 ==========
@@ -622,103 +968,159 @@ func (s *Store) Close() error {
 	return s.redis.Close()
 }
 
-// Health 健康检查
+// healthTimeout Health 检查每个依赖的超时
+const healthTimeout = 5 * time.Second
+
+// Health 健康检查：依次检查数据库和缓存连接
 func (s *Store) Health() error {
-	// 检查 PostgreSQL 连接
-	// 这里可以添加 PostgreSQL 健康检查逻辑
+	ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+	defer cancel()
+	if err := s.PingDatabase(ctx); err != nil {
+		return fmt.Errorf("database: %w", err)
+	}
+	if err := s.PingCache(ctx); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
+}
 
-	// 检查 Redis 连接
-	// 这里可以添加 Redis 健康检查逻辑
+// PingDatabase 检查数据库连接（就绪检查用）
+func (s *Store) PingDatabase(ctx context.Context) error {
+	return s.postgres.Ping(ctx)
+}
 
-	return nil
+// PingCache 检查缓存连接（单机模式的进程内缓存总是可用）
+func (s *Store) PingCache(ctx context.Context) error {
+	return s.redis.Ping(ctx)
 }
 
 // ========== Webhook Repository ==========
 
-func (s *Store) CreateWebhook(webhook *domain.Webhook) error {
-	return s.postgres.CreateWebhook(webhook)
+func (s *Store) CreateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	return s.postgres.CreateWebhook(ctx, webhook)
+}
+
+func (s *Store) GetWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
+	return s.postgres.GetWebhook(ctx, id)
+}
+
+func (s *Store) ListWebhooks(ctx context.Context, userID string) ([]domain.Webhook, error) {
+	return s.postgres.ListWebhooks(ctx, userID)
+}
+
+func (s *Store) ListWebhooksByMailbox(ctx context.Context, mailboxID string) ([]domain.Webhook, error) {
+	return s.postgres.ListWebhooksByMailbox(ctx, mailboxID)
 }
 
-func (s *Store) GetWebhook(id string) (*domain.Webhook, error) {
-	return s.postgres.GetWebhook(id)
+func (s *Store) ListWebhooksByOrgID(ctx context.Context, orgID string) ([]domain.Webhook, error) {
+	return s.postgres.ListWebhooksByOrgID(ctx, orgID)
 }
 
-func (s *Store) ListWebhooks(userID string) ([]domain.Webhook, error) {
-	return s.postgres.ListWebhooks(userID)
+func (s *Store) UpdateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	return s.postgres.UpdateWebhook(ctx, webhook)
 }
 
-func (s *Store) UpdateWebhook(webhook *domain.Webhook) error {
-	return s.postgres.UpdateWebhook(webhook)
+func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
+	return s.postgres.DeleteWebhook(ctx, id)
 }
 
-func (s *Store) DeleteWebhook(id string) error {
-	return s.postgres.DeleteWebhook(id)
+func (s *Store) RecordDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return s.postgres.RecordDelivery(ctx, delivery)
 }
 
-func (s *Store) RecordDelivery(delivery *domain.WebhookDelivery) error {
-	return s.postgres.RecordDelivery(delivery)
+func (s *Store) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	return s.postgres.GetDeliveries(ctx, webhookID, limit)
 }
 
-func (s *Store) GetDeliveries(webhookID string, limit int) ([]domain.WebhookDelivery, error) {
-	return s.postgres.GetDeliveries(webhookID, limit)
+func (s *Store) GetPendingDeliveries(ctx context.Context, limit int) ([]domain.WebhookDelivery, error) {
+	return s.postgres.GetPendingDeliveries(ctx, limit)
 }
 
-func (s *Store) GetPendingDeliveries(limit int) ([]domain.WebhookDelivery, error) {
-	return s.postgres.GetPendingDeliveries(limit)
+// CancelPendingDeliveries 取消邮箱的待重试投递
+func (s *Store) CancelPendingDeliveries(ctx context.Context, mailboxID string) (int, error) {
+	return s.postgres.CancelPendingDeliveries(ctx, mailboxID)
+}
+
+// GetDelivery 获取投递记录
+func (s *Store) GetDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	return s.postgres.GetDelivery(ctx, id)
+}
+
+// UpdateDelivery 更新投递记录
+func (s *Store) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return s.postgres.UpdateDelivery(ctx, delivery)
+}
+
+// ListDeadLetters 列出未重新投递的死信
+func (s *Store) ListDeadLetters(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	return s.postgres.ListDeadLetters(ctx, webhookID, limit)
 }
 
 // ========== Tag Repository ==========
 
-func (s *Store) CreateTag(tag *domain.Tag) error {
-	return s.postgres.CreateTag(tag)
+func (s *Store) CreateTag(ctx context.Context, tag *domain.Tag) error {
+	return s.postgres.CreateTag(ctx, tag)
 }
 
-func (s *Store) GetTag(id string) (*domain.Tag, error) {
-	return s.postgres.GetTag(id)
+func (s *Store) GetTag(ctx context.Context, id string) (*domain.Tag, error) {
+	return s.postgres.GetTag(ctx, id)
 }
 
-func (s *Store) GetTagByName(userID, name string) (*domain.Tag, error) {
-	return s.postgres.GetTagByName(userID, name)
+func (s *Store) GetTagByName(ctx context.Context, userID, name string) (*domain.Tag, error) {
+	return s.postgres.GetTagByName(ctx, userID, name)
 }
 
-func (s *Store) ListTags(userID string) ([]domain.TagWithCount, error) {
-	return s.postgres.ListTags(userID)
+func (s *Store) ListTags(ctx context.Context, userID string) ([]domain.TagWithCount, error) {
+	return s.postgres.ListTags(ctx, userID)
 }
 
-func (s *Store) UpdateTag(tag *domain.Tag) error {
-	return s.postgres.UpdateTag(tag)
+func (s *Store) ListTagsByOrgID(ctx context.Context, orgID string) ([]domain.TagWithCount, error) {
+	return s.postgres.ListTagsByOrgID(ctx, orgID)
 }
 
-func (s *Store) DeleteTag(id string) error {
-	return s.postgres.DeleteTag(id)
+func (s *Store) UpdateTag(ctx context.Context, tag *domain.Tag) error {
+	return s.postgres.UpdateTag(ctx, tag)
 }
 
-func (s *Store) AddMessageTag(messageID, tagID string) error {
-	return s.postgres.AddMessageTag(messageID, tagID)
+func (s *Store) DeleteTag(ctx context.Context, id string) error {
+	return s.postgres.DeleteTag(ctx, id)
 }
 
-func (s *Store) RemoveMessageTag(messageID, tagID string) error {
-	return s.postgres.RemoveMessageTag(messageID, tagID)
+func (s *Store) AddMessageTag(ctx context.Context, messageID, tagID string) error {
+	return s.postgres.AddMessageTag(ctx, messageID, tagID)
 }
 
-func (s *Store) GetMessageTags(messageID string) ([]domain.Tag, error) {
-	return s.postgres.GetMessageTags(messageID)
+func (s *Store) RemoveMessageTag(ctx context.Context, messageID, tagID string) error {
+	return s.postgres.RemoveMessageTag(ctx, messageID, tagID)
 }
 
-func (s *Store) ListMessagesByTag(tagID string) ([]domain.Message, error) {
-	return s.postgres.ListMessagesByTag(tagID)
+func (s *Store) GetMessageTags(ctx context.Context, messageID string) ([]domain.Tag, error) {
+	return s.postgres.GetMessageTags(ctx, messageID)
 }
 
-func (s *Store) DeleteMessageTags(messageID string) error {
-	return s.postgres.DeleteMessageTags(messageID)
+func (s *Store) ListMessagesByTag(ctx context.Context, tagID string) ([]domain.Message, error) {
+	return s.postgres.ListMessagesByTag(ctx, tagID)
+}
+
+func (s *Store) DeleteMessageTags(ctx context.Context, messageID string) error {
+	return s.postgres.DeleteMessageTags(ctx, messageID)
 }
 
 // ========== System Config Repository ==========
 
-func (s *Store) GetSystemConfig() (*domain.SystemConfig, error) {
-	return s.postgres.GetSystemConfig()
+// GetSystemConfig 获取系统配置（优先读取 Redis，多实例共享同一份配置）
+func (s *Store) GetSystemConfig(ctx context.Context) (*domain.SystemConfig, error) {
+	if config, err := s.redis.GetCachedConfig(); err == nil {
+		return config, nil
+	}
+	return s.postgres.GetSystemConfig(ctx)
 }
 
-func (s *Store) SaveSystemConfig(config *domain.SystemConfig) error {
-	return s.postgres.SaveSystemConfig(config)
+// SaveSystemConfig 保存系统配置，并写入 Redis 供其他实例读取
+func (s *Store) SaveSystemConfig(ctx context.Context, config *domain.SystemConfig) error {
+	if err := s.postgres.SaveSystemConfig(ctx, config); err != nil {
+		return err
+	}
+	// 不设置过期时间：配置需要在所有实例间持久共享
+	return s.redis.CacheConfig(config, 0)
 }
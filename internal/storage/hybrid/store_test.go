@@ -0,0 +1,487 @@
+package hybrid
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/cachekey"
+	"tempmail/backend/internal/storage/postgres"
+)
+
+// spyDatabase 记录查询次数的数据库桩（查询带延迟，模拟慢查询）
+type spyDatabase struct {
+	database
+
+	mu        sync.Mutex
+	mailboxes map[string]*domain.Mailbox
+	messages  map[string]int // mailboxID -> 邮件数
+	delay     time.Duration
+
+	afterAddressMiss func() // 按地址查询未命中、返回之前调用（模拟与之交错的写入）
+
+	getMailboxCalls        atomic.Int64
+	getMailboxByAddrCalls  atomic.Int64
+	getSystemDomainByCalls atomic.Int64
+	listSummariesCalls     atomic.Int64
+}
+
+func newSpyDatabase() *spyDatabase {
+	return &spyDatabase{mailboxes: make(map[string]*domain.Mailbox), messages: make(map[string]int), delay: 50 * time.Millisecond}
+}
+
+func (d *spyDatabase) SaveMessage(ctx context.Context, message *domain.Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.messages[message.MailboxID]++
+	return nil
+}
+
+func (d *spyDatabase) ListMailboxSummariesByUserID(ctx context.Context, userID string) ([]domain.MailboxSummary, error) {
+	d.listSummariesCalls.Add(1)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	summaries := make([]domain.MailboxSummary, 0)
+	for _, mailbox := range d.mailboxes {
+		if mailbox.UserID != nil && *mailbox.UserID == userID {
+			summary := domain.MailboxSummary{Mailbox: *mailbox}
+			summary.TotalCount = d.messages[mailbox.ID]
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries, nil
+}
+
+func (d *spyDatabase) SaveMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	copied := *mailbox
+	d.mailboxes[mailbox.ID] = &copied
+	return nil
+}
+
+func (d *spyDatabase) GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error) {
+	d.getMailboxCalls.Add(1)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(d.delay):
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	mailbox, ok := d.mailboxes[id]
+	if !ok {
+		return nil, postgres.ErrMailboxNotFound
+	}
+	copied := *mailbox
+	return &copied, nil
+}
+
+func (d *spyDatabase) CreateMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	return d.SaveMailbox(ctx, mailbox)
+}
+
+func (d *spyDatabase) GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error) {
+	d.getMailboxByAddrCalls.Add(1)
+	d.mu.Lock()
+	for _, mailbox := range d.mailboxes {
+		if mailbox.Address == address {
+			copied := *mailbox
+			d.mu.Unlock()
+			return &copied, nil
+		}
+	}
+	hook := d.afterAddressMiss
+	d.mu.Unlock()
+	if hook != nil {
+		hook()
+	}
+	return nil, postgres.ErrMailboxNotFound
+}
+
+func (d *spyDatabase) GetSystemDomainByDomain(ctx context.Context, domainName string) (*domain.SystemDomain, error) {
+	d.getSystemDomainByCalls.Add(1)
+	return nil, postgres.ErrSystemDomainNotFound
+}
+
+func (d *spyDatabase) SaveSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
+	return nil
+}
+
+func (d *spyDatabase) UpdateSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
+	return nil
+}
+
+func (d *spyDatabase) DeleteSystemDomain(ctx context.Context, domainID string) error { return nil }
+
+func (d *spyDatabase) ExtendMailbox(ctx context.Context, mailboxID string, expiresAt time.Time) error {
+	return nil
+}
+
+// fakeCache 内存版缓存桩
+type fakeCache struct {
+	cache
+
+	mu        sync.Mutex
+	mailboxes map[string]*domain.Mailbox
+	notFound  map[cachekey.Key]bool
+	summaries map[string][]domain.MailboxSummary
+	deleted   []cachekey.Key
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{
+		mailboxes: make(map[string]*domain.Mailbox),
+		notFound:  make(map[cachekey.Key]bool),
+		summaries: make(map[string][]domain.MailboxSummary),
+	}
+}
+
+func (c *fakeCache) Delete(keys ...cachekey.Key) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		c.deleted = append(c.deleted, key)
+		delete(c.notFound, key)
+		for id := range c.mailboxes {
+			if cachekey.Mailbox(id) == key {
+				delete(c.mailboxes, id)
+			}
+		}
+		for userID := range c.summaries {
+			if cachekey.MailboxSummaries(userID) == key {
+				delete(c.summaries, userID)
+			}
+		}
+	}
+	return nil
+}
+
+// deletedKeys 返回已删除的键并清空记录
+func (c *fakeCache) deletedKeys() []cachekey.Key {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := c.deleted
+	c.deleted = nil
+	return keys
+}
+
+func (c *fakeCache) DeleteCachedMessages(mailboxID string) error { return nil }
+
+func (c *fakeCache) CacheMessage(message *domain.Message, ttl time.Duration) error { return nil }
+
+func (c *fakeCache) CacheMailboxSummaries(userID string, summaries []domain.MailboxSummary, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.summaries[userID] = summaries
+	return nil
+}
+
+func (c *fakeCache) GetCachedMailboxSummaries(userID string) ([]domain.MailboxSummary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	summaries, ok := c.summaries[userID]
+	if !ok {
+		return nil, errors.New("cache miss")
+	}
+	return summaries, nil
+}
+
+func (c *fakeCache) CacheMailbox(mailbox *domain.Mailbox, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	copied := *mailbox
+	c.mailboxes[mailbox.ID] = &copied
+	return nil
+}
+
+func (c *fakeCache) GetCachedMailbox(mailboxID string) (*domain.Mailbox, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	mailbox, ok := c.mailboxes[mailboxID]
+	if !ok {
+		return nil, errors.New("cache miss")
+	}
+	copied := *mailbox
+	return &copied, nil
+}
+
+func (c *fakeCache) MarkNotFound(key cachekey.Key, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notFound[cachekey.NotFound(key)] = true
+	return nil
+}
+
+func (c *fakeCache) IsNotFound(key cachekey.Key) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.notFound[cachekey.NotFound(key)], nil
+}
+
+// fakeCacheMetrics 记录缓存指标
+type fakeCacheMetrics struct {
+	coalesced   atomic.Int64
+	negativeHit atomic.Int64
+	hits        atomic.Int64
+	misses      atomic.Int64
+}
+
+func (m *fakeCacheMetrics) RecordCacheCoalesced(operation string)   { m.coalesced.Add(1) }
+func (m *fakeCacheMetrics) RecordCacheNegativeHit(operation string) { m.negativeHit.Add(1) }
+
+func (m *fakeCacheMetrics) RecordCacheLookup(operation string, hit bool) {
+	if hit {
+		m.hits.Add(1)
+	} else {
+		m.misses.Add(1)
+	}
+}
+
+func newTestStore() (*Store, *spyDatabase, *fakeCache, *fakeCacheMetrics) {
+	db := newSpyDatabase()
+	c := newFakeCache()
+	metrics := &fakeCacheMetrics{}
+	store := &Store{postgres: db, redis: c, negatives: newNegativeGuard()}
+	store.SetCacheMetrics(metrics)
+	return store, db, c, metrics
+}
+
+func TestStore_GetMailboxCoalescing(t *testing.T) {
+	store, db, _, metrics := newTestStore()
+	require.NoError(t, db.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-1", Address: "a@temp.mail"}))
+
+	const concurrency = 100
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	results := make([]*domain.Mailbox, concurrency)
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = store.GetMailbox(t.Context(), "mb-1")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), db.getMailboxCalls.Load())
+	for i := 0; i < concurrency; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, "a@temp.mail", results[i].Address)
+	}
+	assert.Positive(t, metrics.coalesced.Load())
+
+	t.Run("每个调用方拿到独立的副本", func(t *testing.T) {
+		seen := make(map[*domain.Mailbox]bool)
+		for _, mailbox := range results {
+			assert.False(t, seen[mailbox])
+			seen[mailbox] = true
+		}
+	})
+}
+
+func TestStore_CoalescingCallerCancel(t *testing.T) {
+	store, db, _, _ := newTestStore()
+	db.delay = 200 * time.Millisecond
+	require.NoError(t, db.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-1", Address: "a@temp.mail"}))
+
+	// 发起查询的请求断开：自己立即返回，等待同一结果的其他请求照常拿到结果
+	leaderCtx, cancel := context.WithCancel(t.Context())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := store.GetMailbox(leaderCtx, "mb-1")
+		leaderErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	followerDone := make(chan struct{})
+	var mailbox *domain.Mailbox
+	var followerErr error
+	go func() {
+		defer close(followerDone)
+		mailbox, followerErr = store.GetMailbox(t.Context(), "mb-1")
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancelledAt := time.Now()
+	cancel()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+	assert.Less(t, time.Since(cancelledAt), 100*time.Millisecond)
+
+	<-followerDone
+	require.NoError(t, followerErr)
+	assert.Equal(t, "a@temp.mail", mailbox.Address)
+	assert.Equal(t, int64(1), db.getMailboxCalls.Load())
+}
+
+func TestStore_NegativeCache(t *testing.T) {
+	t.Run("负缓存抑制重复查询", func(t *testing.T) {
+		store, db, _, metrics := newTestStore()
+
+		for i := 0; i < 5; i++ {
+			_, err := store.GetMailboxByAddress(t.Context(), "missing@temp.mail")
+			assert.ErrorIs(t, err, postgres.ErrMailboxNotFound)
+		}
+		assert.Equal(t, int64(1), db.getMailboxByAddrCalls.Load())
+		assert.Equal(t, int64(4), metrics.negativeHit.Load())
+
+		for i := 0; i < 3; i++ {
+			_, err := store.GetSystemDomainByDomain(t.Context(), "missing.example")
+			assert.ErrorIs(t, err, postgres.ErrSystemDomainNotFound)
+		}
+		assert.Equal(t, int64(1), db.getSystemDomainByCalls.Load())
+	})
+
+	t.Run("负缓存命中后创建邮箱立即可见", func(t *testing.T) {
+		store, _, c, _ := newTestStore()
+
+		_, err := store.GetMailboxByAddress(t.Context(), "new@temp.mail")
+		require.ErrorIs(t, err, postgres.ErrMailboxNotFound)
+		_, err = store.GetMailboxByAddress(t.Context(), "new@temp.mail")
+		require.ErrorIs(t, err, postgres.ErrMailboxNotFound)
+
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-new", Address: "new@temp.mail"}))
+		assert.Empty(t, c.notFound)
+
+		mailbox, err := store.GetMailboxByAddress(t.Context(), "new@temp.mail")
+		require.NoError(t, err)
+		assert.Equal(t, "mb-new", mailbox.ID)
+	})
+
+	t.Run("查库未命中与创建交错时不写入过期的负缓存", func(t *testing.T) {
+		store, db, c, _ := newTestStore()
+		// 查库已返回“不存在”，写入负缓存之前邮箱被创建并清除负缓存
+		db.afterAddressMiss = func() {
+			require.NoError(t, store.CreateMailbox(t.Context(), &domain.Mailbox{ID: "mb-race", Address: "race@temp.mail"}))
+		}
+		_, err := store.GetMailboxByAddress(t.Context(), "race@temp.mail")
+		require.ErrorIs(t, err, postgres.ErrMailboxNotFound, "本次查询早于创建")
+		db.afterAddressMiss = nil
+
+		hit, err := c.IsNotFound(cachekey.MailboxAddress("race@temp.mail"))
+		require.NoError(t, err)
+		assert.False(t, hit, "没有留下过期的负缓存")
+		mailbox, err := store.GetMailboxByAddress(t.Context(), "race@temp.mail")
+		require.NoError(t, err)
+		assert.Equal(t, "mb-race", mailbox.ID)
+
+		_, err = store.GetMailboxByAddress(t.Context(), "other@temp.mail")
+		require.ErrorIs(t, err, postgres.ErrMailboxNotFound)
+		hit, err = c.IsNotFound(cachekey.MailboxAddress("other@temp.mail"))
+		require.NoError(t, err)
+		assert.True(t, hit, "没有交错的查询照常写入负缓存")
+	})
+}
+
+func TestStore_MailboxSummariesCache(t *testing.T) {
+	store, db, _, _ := newTestStore()
+	db.delay = 0
+	userID := "user-1"
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-user", Address: "u@temp.mail", UserID: &userID}))
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-guest", Address: "g@temp.mail"}))
+
+	summaries, err := store.ListMailboxSummariesByUserID(t.Context(), userID)
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	_, err = store.ListMailboxSummariesByUserID(t.Context(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), db.listSummariesCalls.Load(), "第二次读取命中缓存")
+
+	t.Run("其他邮箱收信不清除缓存", func(t *testing.T) {
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{ID: "msg-guest", MailboxID: "mb-guest"}))
+		_, err := store.ListMailboxSummariesByUserID(t.Context(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), db.listSummariesCalls.Load())
+	})
+
+	t.Run("用户邮箱收信后重新统计", func(t *testing.T) {
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{ID: "msg-user", MailboxID: "mb-user"}))
+		summaries, err := store.ListMailboxSummariesByUserID(t.Context(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), db.listSummariesCalls.Load())
+		require.Len(t, summaries, 1)
+		assert.Equal(t, 1, summaries[0].TotalCount)
+	})
+}
+
+func TestStore_CacheInvalidation(t *testing.T) {
+	t.Run("保存与更新系统域名失效同一组键", func(t *testing.T) {
+		store, _, c, _ := newTestStore()
+		sysDomain := &domain.SystemDomain{ID: "sd-1", Domain: "temp.mail"}
+
+		require.NoError(t, store.SaveSystemDomain(t.Context(), sysDomain))
+		saved := c.deletedKeys()
+		require.NoError(t, store.UpdateSystemDomain(t.Context(), sysDomain))
+		updated := c.deletedKeys()
+
+		assert.ElementsMatch(t, saved, updated)
+		assert.ElementsMatch(t, []cachekey.Key{
+			cachekey.SystemDomain("sd-1"),
+			cachekey.SystemDomainList(),
+			cachekey.DefaultSystemDomain(),
+			cachekey.NotFound(cachekey.SystemDomainName("temp.mail")),
+		}, updated)
+	})
+
+	t.Run("删除系统域名同时失效默认域名", func(t *testing.T) {
+		store, _, c, _ := newTestStore()
+
+		require.NoError(t, store.DeleteSystemDomain(t.Context(), "sd-1"))
+		keys := c.deletedKeys()
+		assert.Contains(t, keys, cachekey.SystemDomain("sd-1"))
+		assert.Contains(t, keys, cachekey.DefaultSystemDomain())
+	})
+
+	t.Run("延长有效期后邮箱与摘要重新加载", func(t *testing.T) {
+		store, db, _, _ := newTestStore()
+		db.delay = 0
+		userID := "user-1"
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-1", Address: "a@temp.mail", UserID: &userID}))
+		_, err := store.ListMailboxSummariesByUserID(t.Context(), userID)
+		require.NoError(t, err)
+
+		require.NoError(t, store.ExtendMailbox(t.Context(), "mb-1", time.Now().Add(time.Hour)))
+		_, err = store.ListMailboxSummariesByUserID(t.Context(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), db.listSummariesCalls.Load())
+
+		getCalls := db.getMailboxCalls.Load()
+		_, err = store.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Equal(t, getCalls+1, db.getMailboxCalls.Load())
+	})
+
+	t.Run("事务中的失效在提交后执行", func(t *testing.T) {
+		c := newFakeCache()
+		pending := &txCache{cache: c}
+		store := &Store{postgres: newSpyDatabase(), redis: pending}
+
+		require.NoError(t, store.DeleteSystemDomain(t.Context(), "sd-1"))
+		assert.Empty(t, c.deletedKeys())
+		pending.flush()
+		assert.Contains(t, c.deletedKeys(), cachekey.SystemDomain("sd-1"))
+	})
+}
+
+func TestStore_CacheHitRatioMetric(t *testing.T) {
+	store, db, _, metrics := newTestStore()
+	db.delay = 0
+	require.NoError(t, db.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-1", Address: "a@temp.mail"}))
+
+	for i := 0; i < 3; i++ {
+		_, err := store.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(1), metrics.misses.Load())
+	assert.Equal(t, int64(2), metrics.hits.Load())
+}
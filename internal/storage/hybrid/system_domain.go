@@ -1,39 +1,39 @@
 package hybrid
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"time"
 
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/cachekey"
+	"tempmail/backend/internal/storage/postgres"
 )
 
 // ========== System Domain Repository ==========
 
 // SaveSystemDomain 保存系统域名
-func (s *Store) SaveSystemDomain(sysDomain *domain.SystemDomain) error {
+func (s *Store) SaveSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
 	// 保存到 PostgreSQL
-	if err := s.postgres.SaveSystemDomain(sysDomain); err != nil {
+	if err := s.postgres.SaveSystemDomain(ctx, sysDomain); err != nil {
 		return err
 	}
 
-	// 缓存到 Redis（1小时过期）
-	s.redis.CacheSystemDomain(sysDomain, 1*time.Hour)
-
-	// 删除系统域名列表缓存（因为列表已变化）
-	s.redis.Delete("system_domains:list")
+	// 失效域名、列表和默认域名缓存，并清除按域名查询的负缓存，新域名立即可见
+	s.invalidateSystemDomains([]string{sysDomain.ID}, sysDomain.Domain)
 
 	return nil
 }
 
 // GetSystemDomain 根据 ID 获取系统域名
-func (s *Store) GetSystemDomain(domainID string) (*domain.SystemDomain, error) {
+func (s *Store) GetSystemDomain(ctx context.Context, domainID string) (*domain.SystemDomain, error) {
 	// 先尝试从 Redis 获取
-	if sysDomain, err := s.redis.GetCachedSystemDomain(domainID); err == nil {
+	if sysDomain, err := s.redis.GetCachedSystemDomain(domainID); s.cacheHit(opGetSystemDomain, err) {
 		return sysDomain, nil
 	}
 
 	// 从 PostgreSQL 获取
-	sysDomain, err := s.postgres.GetSystemDomain(domainID)
+	sysDomain, err := s.postgres.GetSystemDomain(ctx, domainID)
 	if err != nil {
 		return nil, err
 	}
@@ -45,20 +45,42 @@ func (s *Store) GetSystemDomain(domainID string) (*domain.SystemDomain, error) {
 }
 
 // GetSystemDomainByDomain 根据域名获取系统域名
-func (s *Store) GetSystemDomainByDomain(domainName string) (*domain.SystemDomain, error) {
-	// 域名查询直接从 PostgreSQL 获取（不缓存，因为查询频繁且变化多）
-	return s.postgres.GetSystemDomainByDomain(domainName)
+func (s *Store) GetSystemDomainByDomain(ctx context.Context, domainName string) (*domain.SystemDomain, error) {
+	// 域名查询不做正向缓存（查询频繁且变化多），只缓存“不存在”的结果
+	key := cachekey.SystemDomainName(domainName)
+	if s.isNegativeCached(opGetSystemDomainByDomain, key) {
+		return nil, postgres.ErrSystemDomainNotFound
+	}
+
+	generation := s.negatives.generation(key)
+	value, leader, err := s.coalesce(ctx, opGetSystemDomainByDomain, domainName, func(ctx context.Context) (interface{}, error) {
+		sysDomain, err := s.postgres.GetSystemDomainByDomain(ctx, domainName)
+		if errors.Is(err, postgres.ErrSystemDomainNotFound) {
+			s.markNotFound(key, generation)
+		}
+		return sysDomain, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sysDomain := value.(*domain.SystemDomain)
+	if !leader {
+		copied := *sysDomain
+		sysDomain = &copied
+	}
+	return sysDomain, nil
 }
 
 // ListSystemDomains 获取所有系统域名
-func (s *Store) ListSystemDomains() ([]*domain.SystemDomain, error) {
+func (s *Store) ListSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
 	// 先尝试从 Redis 获取
-	if sysDomains, err := s.redis.GetCachedSystemDomainList(); err == nil {
+	if sysDomains, err := s.redis.GetCachedSystemDomainList(); s.cacheHit(opListSystemDomains, err) {
 		return sysDomains, nil
 	}
 
 	// 从 PostgreSQL 获取
-	sysDomains, err := s.postgres.ListSystemDomains()
+	sysDomains, err := s.postgres.ListSystemDomains(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -70,64 +92,81 @@ func (s *Store) ListSystemDomains() ([]*domain.SystemDomain, error) {
 }
 
 // ListActiveSystemDomains 获取所有已激活的系统域名
-func (s *Store) ListActiveSystemDomains() ([]*domain.SystemDomain, error) {
-	// 活跃域名查询直接从 PostgreSQL 获取（不缓存）
-	return s.postgres.ListActiveSystemDomains()
+func (s *Store) ListActiveSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
+	// 活跃域名查询直接从 PostgreSQL 获取（不缓存，并发查询合并为一次）
+	value, leader, err := s.coalesce(ctx, opListActiveSystemDomains, "", func(ctx context.Context) (interface{}, error) {
+		return s.postgres.ListActiveSystemDomains(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sysDomains := value.([]*domain.SystemDomain)
+	if leader {
+		return sysDomains, nil
+	}
+	copied := make([]*domain.SystemDomain, len(sysDomains))
+	for i, sysDomain := range sysDomains {
+		item := *sysDomain
+		copied[i] = &item
+	}
+	return copied, nil
 }
 
 // UpdateSystemDomain 更新系统域名
-func (s *Store) UpdateSystemDomain(sysDomain *domain.SystemDomain) error {
+func (s *Store) UpdateSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
 	// 更新 PostgreSQL
-	if err := s.postgres.UpdateSystemDomain(sysDomain); err != nil {
+	if err := s.postgres.UpdateSystemDomain(ctx, sysDomain); err != nil {
 		return err
 	}
 
-	// 更新 Redis 缓存
-	s.redis.CacheSystemDomain(sysDomain, 1*time.Hour)
-
-	// 删除系统域名列表缓存
-	s.redis.Delete("system_domains:list")
+	// 与保存相同的失效集合：域名可能被修改（清除负缓存），也可能是默认域名
+	s.invalidateSystemDomains([]string{sysDomain.ID}, sysDomain.Domain)
 
 	return nil
 }
 
 // DeleteSystemDomain 删除系统域名
-func (s *Store) DeleteSystemDomain(domainID string) error {
+func (s *Store) DeleteSystemDomain(ctx context.Context, domainID string) error {
 	// 从 PostgreSQL 删除
-	if err := s.postgres.DeleteSystemDomain(domainID); err != nil {
+	if err := s.postgres.DeleteSystemDomain(ctx, domainID); err != nil {
 		return err
 	}
 
-	// 删除 Redis 缓存
-	s.redis.Delete(fmt.Sprintf("system_domain:%s", domainID))
-	s.redis.Delete("system_domains:list")
+	// 删除 Redis 缓存（被删除的可能是默认域名）
+	s.invalidateSystemDomains([]string{domainID})
 
 	return nil
 }
 
 // SetDefaultSystemDomain 设置默认系统域名
-func (s *Store) SetDefaultSystemDomain(domainID string) error {
+func (s *Store) SetDefaultSystemDomain(ctx context.Context, domainID string) error {
+	// 记下原默认域名：它缓存的实体上仍带着默认标记
+	domainIDs := []string{domainID}
+	if previous, err := s.GetDefaultSystemDomain(ctx); err == nil && previous.ID != domainID {
+		domainIDs = append(domainIDs, previous.ID)
+	}
+
 	// 更新 PostgreSQL
-	if err := s.postgres.SetDefaultSystemDomain(domainID); err != nil {
+	if err := s.postgres.SetDefaultSystemDomain(ctx, domainID); err != nil {
 		return err
 	}
 
 	// 删除相关缓存（强制重新加载）
-	s.redis.Delete("system_domains:list")
-	s.redis.Delete("system_domain:default")
+	s.invalidateSystemDomains(domainIDs)
 
 	return nil
 }
 
 // GetDefaultSystemDomain 获取默认系统域名
-func (s *Store) GetDefaultSystemDomain() (*domain.SystemDomain, error) {
+func (s *Store) GetDefaultSystemDomain(ctx context.Context) (*domain.SystemDomain, error) {
 	// 先尝试从 Redis 获取
-	if sysDomain, err := s.redis.GetCachedDefaultSystemDomain(); err == nil {
+	if sysDomain, err := s.redis.GetCachedDefaultSystemDomain(); s.cacheHit(opGetDefaultSystemDomain, err) {
 		return sysDomain, nil
 	}
 
 	// 从 PostgreSQL 获取
-	sysDomain, err := s.postgres.GetDefaultSystemDomain()
+	sysDomain, err := s.postgres.GetDefaultSystemDomain(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -139,41 +178,41 @@ func (s *Store) GetDefaultSystemDomain() (*domain.SystemDomain, error) {
 }
 
 // IncrementSystemDomainMailboxCount 增加系统域名邮箱计数
-func (s *Store) IncrementSystemDomainMailboxCount(domainName string) error {
+func (s *Store) IncrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
 	// 更新 PostgreSQL
-	if err := s.postgres.IncrementSystemDomainMailboxCount(domainName); err != nil {
+	if err := s.postgres.IncrementSystemDomainMailboxCount(ctx, domainName); err != nil {
 		return err
 	}
 
-	// 删除相关缓存（强制重新加载）
-	s.redis.Delete("system_domains:list")
+	// 删除相关缓存（强制重新加载；计数也出现在默认域名中）
+	s.invalidateSystemDomains(nil)
 
 	return nil
 }
 
 // DecrementSystemDomainMailboxCount 减少系统域名邮箱计数
-func (s *Store) DecrementSystemDomainMailboxCount(domainName string) error {
+func (s *Store) DecrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
 	// 更新 PostgreSQL
-	if err := s.postgres.DecrementSystemDomainMailboxCount(domainName); err != nil {
+	if err := s.postgres.DecrementSystemDomainMailboxCount(ctx, domainName); err != nil {
 		return err
 	}
 
-	// 删除相关缓存（强制重新加载）
-	s.redis.Delete("system_domains:list")
+	// 删除相关缓存（强制重新加载；计数也出现在默认域名中）
+	s.invalidateSystemDomains(nil)
 
 	return nil
 }
 
 // DeleteUnverifiedSystemDomains 删除指定时间前创建且未验证的域名
-func (s *Store) DeleteUnverifiedSystemDomains(before time.Time) (int, error) {
+func (s *Store) DeleteUnverifiedSystemDomains(ctx context.Context, before time.Time) (int, error) {
 	// 直接从 PostgreSQL 删除
-	count, err := s.postgres.DeleteUnverifiedSystemDomains(before)
+	count, err := s.postgres.DeleteUnverifiedSystemDomains(ctx, before)
 	if err != nil {
 		return 0, err
 	}
 
 	// 删除相关缓存
-	s.redis.Delete("system_domains:list")
+	s.invalidateSystemDomains(nil)
 
 	return count, nil
 }
\ No newline at end of file
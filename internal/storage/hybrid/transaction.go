@@ -0,0 +1,97 @@
+package hybrid
+
+import (
+	"sync"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+	"tempmail/backend/internal/storage/cachekey"
+)
+
+// WithTransaction 在数据库事务中执行 fn
+//
+// 事务内的缓存写入先暂存，提交成功后再写入 Redis；回滚时丢弃，避免缓存中出现未提交的数据。
+func (s *Store) WithTransaction(fn func(tx storage.Store) error) error {
+	pending := &txCache{cache: s.redis, negatives: s.negatives}
+	err := s.postgres.WithTransaction(func(dbTx database) error {
+		return fn(&Store{postgres: dbTx, redis: pending, ctx: s.ctx, negatives: s.negatives, metrics: s.metrics})
+	})
+	if err != nil {
+		return err
+	}
+	pending.flush()
+	return nil
+}
+
+// txCache 事务内使用的缓存：读操作直接访问 Redis，写操作延迟到事务提交后执行
+type txCache struct {
+	cache
+	negatives *negativeGuard // 提交后执行失效时再次递增负缓存代数（提交前回源的查询可能仍未看到新数据）
+
+	mu     sync.Mutex
+	writes []func(c cache) error
+}
+
+// enqueue 暂存一次缓存写入
+func (t *txCache) enqueue(write func(c cache) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.writes = append(t.writes, write)
+	return nil
+}
+
+// flush 按顺序执行暂存的缓存写入（缓存失败不影响已提交的数据）
+func (t *txCache) flush() {
+	t.mu.Lock()
+	writes := t.writes
+	t.writes = nil
+	t.mu.Unlock()
+
+	for _, write := range writes {
+		write(t.cache)
+	}
+}
+
+func (t *txCache) CacheSystemDomain(sysDomain *domain.SystemDomain, ttl time.Duration) error {
+	return t.enqueue(func(c cache) error { return c.CacheSystemDomain(sysDomain, ttl) })
+}
+
+func (t *txCache) CacheSystemDomainList(sysDomains []*domain.SystemDomain, ttl time.Duration) error {
+	return t.enqueue(func(c cache) error { return c.CacheSystemDomainList(sysDomains, ttl) })
+}
+
+func (t *txCache) CacheDefaultSystemDomain(sysDomain *domain.SystemDomain, ttl time.Duration) error {
+	return t.enqueue(func(c cache) error { return c.CacheDefaultSystemDomain(sysDomain, ttl) })
+}
+
+func (t *txCache) CacheConfig(config *domain.SystemConfig, ttl time.Duration) error {
+	return t.enqueue(func(c cache) error { return c.CacheConfig(config, ttl) })
+}
+
+func (t *txCache) CacheMailbox(mailbox *domain.Mailbox, ttl time.Duration) error {
+	return t.enqueue(func(c cache) error { return c.CacheMailbox(mailbox, ttl) })
+}
+
+func (t *txCache) CacheMessage(message *domain.Message, ttl time.Duration) error {
+	return t.enqueue(func(c cache) error { return c.CacheMessage(message, ttl) })
+}
+
+func (t *txCache) CacheMessageStats(key string, stats *domain.MessageStats, ttl time.Duration) error {
+	return t.enqueue(func(c cache) error { return c.CacheMessageStats(key, stats, ttl) })
+}
+
+func (t *txCache) Delete(keys ...cachekey.Key) error {
+	return t.enqueue(func(c cache) error {
+		t.negatives.bump(keys...)
+		return c.Delete(keys...)
+	})
+}
+
+func (t *txCache) DeleteCachedMessages(mailboxID string) error {
+	return t.enqueue(func(c cache) error { return c.DeleteCachedMessages(mailboxID) })
+}
+
+func (t *txCache) MarkNotFound(key cachekey.Key, ttl time.Duration) error {
+	return t.enqueue(func(c cache) error { return c.MarkNotFound(key, ttl) })
+}
@@ -0,0 +1,36 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== User Session Repository ==========
+//
+// 登录会话只在登录、刷新和注销时读写，直接访问 PostgreSQL，不进入缓存（吊销的访问令牌 jti 在 Redis 黑名单中）。
+
+func (s *Store) CreateUserSession(ctx context.Context, session *domain.UserSession) error {
+	return s.postgres.CreateUserSession(ctx, session)
+}
+
+func (s *Store) GetUserSession(ctx context.Context, id string) (*domain.UserSession, error) {
+	return s.postgres.GetUserSession(ctx, id)
+}
+
+func (s *Store) RotateUserSession(ctx context.Context, session *domain.UserSession, previousRefreshID string) error {
+	return s.postgres.RotateUserSession(ctx, session, previousRefreshID)
+}
+
+func (s *Store) ListUserSessions(ctx context.Context, userID string) ([]*domain.UserSession, error) {
+	return s.postgres.ListUserSessions(ctx, userID)
+}
+
+func (s *Store) DeleteUserSession(ctx context.Context, id string) error {
+	return s.postgres.DeleteUserSession(ctx, id)
+}
+
+func (s *Store) DeleteExpiredUserSessions(ctx context.Context, now time.Time) (int64, error) {
+	return s.postgres.DeleteExpiredUserSessions(ctx, now)
+}
@@ -0,0 +1,46 @@
+package instrumented
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// 以下为部分存储实现才有的可选能力，包装后需保持同样的行为：
+// 被包装的实现不支持时，退回到业务层原本的降级方式。
+
+// WithTransaction 在事务中执行（事务内的调用同样计时）
+func (s *Store) WithTransaction(fn func(tx storage.Store) error) error {
+	transactor, ok := s.inner.(interface {
+		WithTransaction(fn func(tx storage.Store) error) error
+	})
+	if !ok {
+		return fn(s)
+	}
+	return transactor.WithTransaction(func(tx storage.Store) error {
+		return fn(&Store{inner: tx, observer: s.observer})
+	})
+}
+
+// UpdateSystemDomain 更新系统域名（不支持时按保存处理）
+func (s *Store) UpdateSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
+	updater, ok := s.inner.(interface {
+		UpdateSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error
+	})
+	if !ok {
+		return s.SaveSystemDomain(ctx, sysDomain)
+	}
+	return updater.UpdateSystemDomain(ctx, sysDomain)
+}
+
+// EvictMailboxCache 清除邮箱缓存（无缓存的实现直接返回）
+func (s *Store) EvictMailboxCache(mailboxID string) error {
+	evictor, ok := s.inner.(interface {
+		EvictMailboxCache(mailboxID string) error
+	})
+	if !ok {
+		return nil
+	}
+	return evictor.EvictMailboxCache(mailboxID)
+}
@@ -0,0 +1,195 @@
+// Package instrumented 为存储层记录调用耗时和慢调用
+//
+// Store 包装任意 storage.Store 实现，每次方法调用按方法名和存储后端记入 Prometheus 直方图，
+// 超过阈值的调用记入内存中的慢调用列表（只保留最慢的 N 条），供管理接口排查。
+// 带上下文的调用同时创建链路追踪的子 span（未启用追踪时为空实现）。
+package instrumented
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"tempmail/backend/internal/tracing"
+)
+
+// DefaultSlowCallCapacity 慢调用列表默认容量
+const DefaultSlowCallCapacity = 50
+
+// maxKeyLength 慢调用记录中参数保留的最大字符数
+const maxKeyLength = 8
+
+// maxSQLLength 慢 SQL 模板保留的最大字符数
+const maxSQLLength = 512
+
+// SourceSQL 慢调用来自 GORM 的 SQL 语句（Method 为空，SQL 为模板）
+const SourceSQL = "sql"
+
+// SlowCall 一次慢调用
+type SlowCall struct {
+	Backend  string        `json:"backend"`
+	Method   string        `json:"method,omitempty"`
+	Key      string        `json:"key,omitempty"` // 截断并脱敏后的首个字符串参数
+	SQL      string        `json:"sql,omitempty"` // SQL 模板（不含参数值）
+	Duration time.Duration `json:"-"`
+	Millis   float64       `json:"durationMs"`
+	Failed   bool          `json:"failed"`
+	At       time.Time     `json:"at"`
+}
+
+// Recorder 存储调用指标和慢调用列表（多个后端共用一个实例）
+type Recorder struct {
+	duration  *prometheus.HistogramVec
+	threshold time.Duration
+
+	mu       sync.Mutex
+	capacity int
+	slowest  []SlowCall // 按耗时降序
+}
+
+// NewRecorder 创建记录器
+//
+// reg 为 nil 时使用默认注册表；threshold 为慢调用阈值，capacity 为慢调用列表容量。
+func NewRecorder(reg prometheus.Registerer, threshold time.Duration, capacity int) *Recorder {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	if capacity <= 0 {
+		capacity = DefaultSlowCallCapacity
+	}
+	return &Recorder{
+		duration: promauto.With(reg).NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "tempmail_store_operation_duration_seconds",
+				Help:    "Storage operation duration in seconds",
+				Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14), // 0.5ms ~ 4s
+			},
+			[]string{"method", "backend", "status"},
+		),
+		threshold: threshold,
+		capacity:  capacity,
+		slowest:   make([]SlowCall, 0, capacity),
+	}
+}
+
+// Observer 创建某个后端的观察器，为每个操作预先分配好标签
+func (r *Recorder) Observer(backend string, operations []string) *Observer {
+	o := &Observer{
+		recorder:   r,
+		backend:    backend,
+		operations: operations,
+		ok:         make([]prometheus.Observer, len(operations)),
+		failed:     make([]prometheus.Observer, len(operations)),
+		spanNames:  make([]string, len(operations)),
+		spanAttrs:  trace.WithAttributes(attribute.String("store.backend", backend)),
+	}
+	for i, name := range operations {
+		o.spanNames[i] = "store." + name
+		o.ok[i] = r.duration.WithLabelValues(name, backend, "ok")
+		o.failed[i] = r.duration.WithLabelValues(name, backend, "error")
+	}
+	return o
+}
+
+// SlowCalls 返回慢调用列表（按耗时降序）
+func (r *Recorder) SlowCalls() []SlowCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]SlowCall(nil), r.slowest...)
+}
+
+// Threshold 慢调用阈值
+func (r *Recorder) Threshold() time.Duration {
+	return r.threshold
+}
+
+// RecordSlowQuery 记录慢 SQL（由数据库存储的 GORM 日志调用，sql 为不含参数值的模板）
+func (r *Recorder) RecordSlowQuery(sql string, elapsed time.Duration, err error) {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > maxSQLLength {
+		sql = sql[:maxSQLLength] + "…"
+	}
+	r.record(SlowCall{Backend: SourceSQL, SQL: sql, Duration: elapsed, Failed: err != nil, At: time.Now()})
+}
+
+// record 插入慢调用，列表已满且比最快的一条还快时丢弃
+func (r *Recorder) record(call SlowCall) {
+	call.Millis = float64(call.Duration.Microseconds()) / 1000
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.slowest) == r.capacity {
+		if call.Duration <= r.slowest[len(r.slowest)-1].Duration {
+			return
+		}
+		r.slowest = r.slowest[:len(r.slowest)-1]
+	}
+	i := sort.Search(len(r.slowest), func(i int) bool { return r.slowest[i].Duration < call.Duration })
+	r.slowest = append(r.slowest, SlowCall{})
+	copy(r.slowest[i+1:], r.slowest[i:])
+	r.slowest[i] = call
+}
+
+// Observer 记录一个后端的调用耗时（操作按下标访问，热路径不做字符串拼接和查表）
+type Observer struct {
+	recorder   *Recorder
+	backend    string
+	operations []string
+	ok         []prometheus.Observer
+	failed     []prometheus.Observer
+	spanNames  []string
+	spanAttrs  trace.SpanStartEventOption
+}
+
+// Start 开始一次调用：返回带子 span 的上下文（传给被包装的实现）和开始时间
+func (o *Observer) Start(ctx context.Context, op int) (context.Context, time.Time) {
+	ctx, _ = tracing.Start(ctx, o.spanNames[op], trace.WithSpanKind(trace.SpanKindClient), o.spanAttrs)
+	return ctx, time.Now()
+}
+
+// End 结束 Start 创建的 span 并记录调用
+func (o *Observer) End(ctx context.Context, op int, start time.Time, err error, key string) {
+	tracing.End(trace.SpanFromContext(ctx), err)
+	o.Observe(op, start, err, key)
+}
+
+// Observe 记录一次调用；key 为首个字符串参数，只在记入慢调用时截断脱敏
+func (o *Observer) Observe(op int, start time.Time, err error, key string) {
+	elapsed := time.Since(start)
+	if err != nil {
+		o.failed[op].Observe(elapsed.Seconds())
+	} else {
+		o.ok[op].Observe(elapsed.Seconds())
+	}
+	if elapsed < o.recorder.threshold {
+		return
+	}
+	o.recorder.record(SlowCall{
+		Backend:  o.backend,
+		Method:   o.operations[op],
+		Key:      redactKey(key),
+		Duration: elapsed,
+		Failed:   err != nil,
+		At:       time.Now(),
+	})
+}
+
+// redactKey 截断参数：邮箱地址只保留本地部分首字符和域名，其他值（ID、令牌等）只保留前几个字符
+func redactKey(key string) string {
+	if at := strings.LastIndexByte(key, '@'); at > 0 {
+		first, _ := utf8.DecodeRuneInString(key)
+		return string(first) + "***" + key[at:]
+	}
+	if utf8.RuneCountInString(key) <= maxKeyLength {
+		return key
+	}
+	return string([]rune(key)[:maxKeyLength]) + "…"
+}
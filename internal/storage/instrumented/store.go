@@ -0,0 +1,1874 @@
+package instrumented
+
+import (
+	"context"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// 存储操作（下标对应 operations 中的方法名）
+const (
+	opSaveMailbox = iota
+	opCreateMailbox
+	opGetMailbox
+	opGetMailboxByAddress
+	opGetMailboxesByAddresses
+	opListMailboxes
+	opListMailboxesByUserID
+	opListMailboxesByOrgID
+	opListMailboxSummariesByUserID
+	opListMailboxSummariesByOrgID
+	opDeleteMailbox
+	opDeleteExpiredMailboxes
+	opListExpiredMailboxes
+	opTouchMailbox
+	opListIdleMailboxes
+	opMarkMailboxIdle
+	opExtendMailbox
+	opListPublicMailboxes
+	opSaveMessage
+	opSaveMessages
+	opListMessages
+	opGetMessage
+	opMarkMessageRead
+	opMarkMessageUnread
+	opDeleteMessage
+	opDeleteAllMessages
+	opSetMessageExpiry
+	opSetMessageQuarantined
+	opDeleteExpiredMessages
+	opSearchMessages
+	opGetMessageStats
+	opSaveAlias
+	opGetAlias
+	opGetAliasByAddress
+	opListAliasesByMailboxID
+	opDeleteAlias
+	opCreateUser
+	opGetUserByID
+	opGetUserByEmail
+	opGetUserByUsername
+	opGetUserByAPIKey
+	opUpdateUser
+	opUpdateLastLogin
+	opListUsers
+	opDeleteUser
+	opDeleteMailboxesByUserID
+	opGetSystemStatistics
+	opGetDomainStatistics
+	opSaveUserDomain
+	opGetUserDomain
+	opGetUserDomainByDomain
+	opListUserDomainsByUserID
+	opListUserDomainsByOrgID
+	opUpdateUserDomain
+	opDeleteUserDomain
+	opIncrementMailboxCount
+	opDecrementMailboxCount
+	opSaveSystemDomain
+	opGetSystemDomain
+	opGetSystemDomainByDomain
+	opListSystemDomains
+	opListActiveSystemDomains
+	opDeleteSystemDomain
+	opIncrementSystemDomainMailboxCount
+	opDecrementSystemDomainMailboxCount
+	opDeleteUnverifiedSystemDomains
+	opSaveAPIKey
+	opGetAPIKey
+	opGetAPIKeyByKey
+	opListAPIKeysByUserID
+	opDeleteAPIKey
+	opUpdateAPIKeyLastUsed
+	opCreateWebhook
+	opGetWebhook
+	opListWebhooks
+	opListWebhooksByMailbox
+	opListWebhooksByOrgID
+	opUpdateWebhook
+	opDeleteWebhook
+	opRecordDelivery
+	opGetDeliveries
+	opGetPendingDeliveries
+	opCancelPendingDeliveries
+	opGetDelivery
+	opUpdateDelivery
+	opListDeadLetters
+	opCreateTag
+	opGetTag
+	opGetTagByName
+	opListTags
+	opListTagsByOrgID
+	opUpdateTag
+	opDeleteTag
+	opAddMessageTag
+	opRemoveMessageTag
+	opGetMessageTags
+	opListMessagesByTag
+	opDeleteMessageTags
+	opCreateOrganization
+	opGetOrganization
+	opSaveOrgMember
+	opGetOrgMember
+	opListOrgMembers
+	opListOrgMembershipsByUserID
+	opDeleteOrgMember
+	opSaveOrgInvite
+	opGetOrgInvite
+	opDeleteOrgInvite
+	opSaveDistributionList
+	opGetDistributionList
+	opGetDistributionListByAddress
+	opListDistributionListsByUserID
+	opListDistributionListsByOrgID
+	opDeleteDistributionList
+	opSaveDistributionListDelivery
+	opListDistributionListDeliveries
+	opSaveMessageRedaction
+	opGetMessageRedaction
+	opSaveAbuseReport
+	opGetAbuseReport
+	opListAbuseReports
+	opSaveMessageShare
+	opGetMessageShare
+	opListMessageShares
+	opRecordMessageShareView
+	opSaveSentMessage
+	opListSentMessages
+	opCountSentMessages
+	opAddDomainWhitelistEntry
+	opListDomainWhitelist
+	opDeleteDomainWhitelistEntry
+	opIsLocalPartWhitelisted
+	opSaveMailboxForward
+	opGetMailboxForward
+	opListMailboxForwards
+	opDeleteMailboxForward
+	opSaveForwardDelivery
+	opListPendingForwardDeliveries
+	opIndexMessages
+	opSearchIndex
+	opClearSearchIndex
+	opCreateMaintenanceJob
+	opGetMaintenanceJob
+	opListMaintenanceJobs
+	opUpdateMaintenanceJob
+	opCountMessages
+	opListMessagesAfter
+	opSetMessageDerivedText
+	opCountMailboxes
+	opListMailboxIDsAfter
+	opRecountMailbox
+	opMergeAnalyticsBuckets
+	opListAnalyticsBuckets
+	opDeleteAnalyticsBucketsBefore
+	opMergeMailFlowCounters
+	opListMailFlowCounters
+	opDeleteMailFlowCountersBefore
+	opListMessagesPage
+	opCreateReservedPrefix
+	opListReservedPrefixes
+	opFindReservedPrefixes
+	opDeleteReservedPrefix
+	opCreateOAuthIdentity
+	opGetOAuthIdentity
+	opTouchOAuthIdentity
+	opListOAuthIdentities
+	opCreateUserSession
+	opGetUserSession
+	opRotateUserSession
+	opListUserSessions
+	opDeleteUserSession
+	opDeleteExpiredUserSessions
+	opCreateAuditLog
+	opListAuditLogs
+	opSaveMessagesWithOutbox
+	opClaimOutboxEvents
+	opUpdateOutboxEvent
+	opDeleteProcessedOutboxEvents
+	opRecordSinkMessage
+	opRecordSinkSample
+	opGetSinkStats
+	opGetSystemConfig
+	opSaveSystemConfig
+	opAddToBlacklist
+	opIsBlacklisted
+	opIncrementRateLimit
+	opGetRateLimit
+	opResetRateLimit
+	opCacheSession
+	opGetCachedSession
+	opDeleteCachedSession
+	opPublishNewMail
+	opSubscribeNewMail
+	opClose
+	opHealth
+	opListAllUserDomains
+)
+
+// operations 操作下标到方法名（指标标签）
+var operations = [...]string{
+	opSaveMailbox:                       "SaveMailbox",
+	opCreateMailbox:                     "CreateMailbox",
+	opGetMailbox:                        "GetMailbox",
+	opGetMailboxByAddress:               "GetMailboxByAddress",
+	opGetMailboxesByAddresses:           "GetMailboxesByAddresses",
+	opListMailboxes:                     "ListMailboxes",
+	opListMailboxesByUserID:             "ListMailboxesByUserID",
+	opListMailboxesByOrgID:              "ListMailboxesByOrgID",
+	opListMailboxSummariesByUserID:      "ListMailboxSummariesByUserID",
+	opListMailboxSummariesByOrgID:       "ListMailboxSummariesByOrgID",
+	opDeleteMailbox:                     "DeleteMailbox",
+	opDeleteExpiredMailboxes:            "DeleteExpiredMailboxes",
+	opListExpiredMailboxes:              "ListExpiredMailboxes",
+	opTouchMailbox:                      "TouchMailbox",
+	opListIdleMailboxes:                 "ListIdleMailboxes",
+	opMarkMailboxIdle:                   "MarkMailboxIdle",
+	opExtendMailbox:                     "ExtendMailbox",
+	opListPublicMailboxes:               "ListPublicMailboxes",
+	opSaveMessage:                       "SaveMessage",
+	opSaveMessages:                      "SaveMessages",
+	opListMessages:                      "ListMessages",
+	opGetMessage:                        "GetMessage",
+	opMarkMessageRead:                   "MarkMessageRead",
+	opMarkMessageUnread:                 "MarkMessageUnread",
+	opDeleteMessage:                     "DeleteMessage",
+	opDeleteAllMessages:                 "DeleteAllMessages",
+	opSetMessageExpiry:                  "SetMessageExpiry",
+	opSetMessageQuarantined:             "SetMessageQuarantined",
+	opDeleteExpiredMessages:             "DeleteExpiredMessages",
+	opSearchMessages:                    "SearchMessages",
+	opGetMessageStats:                   "GetMessageStats",
+	opSaveAlias:                         "SaveAlias",
+	opGetAlias:                          "GetAlias",
+	opGetAliasByAddress:                 "GetAliasByAddress",
+	opListAliasesByMailboxID:            "ListAliasesByMailboxID",
+	opDeleteAlias:                       "DeleteAlias",
+	opCreateUser:                        "CreateUser",
+	opGetUserByID:                       "GetUserByID",
+	opGetUserByEmail:                    "GetUserByEmail",
+	opGetUserByUsername:                 "GetUserByUsername",
+	opGetUserByAPIKey:                   "GetUserByAPIKey",
+	opUpdateUser:                        "UpdateUser",
+	opUpdateLastLogin:                   "UpdateLastLogin",
+	opListUsers:                         "ListUsers",
+	opDeleteUser:                        "DeleteUser",
+	opDeleteMailboxesByUserID:           "DeleteMailboxesByUserID",
+	opGetSystemStatistics:               "GetSystemStatistics",
+	opGetDomainStatistics:               "GetDomainStatistics",
+	opSaveUserDomain:                    "SaveUserDomain",
+	opGetUserDomain:                     "GetUserDomain",
+	opGetUserDomainByDomain:             "GetUserDomainByDomain",
+	opListUserDomainsByUserID:           "ListUserDomainsByUserID",
+	opListUserDomainsByOrgID:            "ListUserDomainsByOrgID",
+	opUpdateUserDomain:                  "UpdateUserDomain",
+	opDeleteUserDomain:                  "DeleteUserDomain",
+	opIncrementMailboxCount:             "IncrementMailboxCount",
+	opDecrementMailboxCount:             "DecrementMailboxCount",
+	opSaveSystemDomain:                  "SaveSystemDomain",
+	opGetSystemDomain:                   "GetSystemDomain",
+	opGetSystemDomainByDomain:           "GetSystemDomainByDomain",
+	opListSystemDomains:                 "ListSystemDomains",
+	opListActiveSystemDomains:           "ListActiveSystemDomains",
+	opDeleteSystemDomain:                "DeleteSystemDomain",
+	opIncrementSystemDomainMailboxCount: "IncrementSystemDomainMailboxCount",
+	opDecrementSystemDomainMailboxCount: "DecrementSystemDomainMailboxCount",
+	opDeleteUnverifiedSystemDomains:     "DeleteUnverifiedSystemDomains",
+	opSaveAPIKey:                        "SaveAPIKey",
+	opGetAPIKey:                         "GetAPIKey",
+	opGetAPIKeyByKey:                    "GetAPIKeyByKey",
+	opListAPIKeysByUserID:               "ListAPIKeysByUserID",
+	opDeleteAPIKey:                      "DeleteAPIKey",
+	opUpdateAPIKeyLastUsed:              "UpdateAPIKeyLastUsed",
+	opCreateWebhook:                     "CreateWebhook",
+	opGetWebhook:                        "GetWebhook",
+	opListWebhooks:                      "ListWebhooks",
+	opListWebhooksByMailbox:             "ListWebhooksByMailbox",
+	opListWebhooksByOrgID:               "ListWebhooksByOrgID",
+	opUpdateWebhook:                     "UpdateWebhook",
+	opDeleteWebhook:                     "DeleteWebhook",
+	opRecordDelivery:                    "RecordDelivery",
+	opGetDeliveries:                     "GetDeliveries",
+	opGetPendingDeliveries:              "GetPendingDeliveries",
+	opCancelPendingDeliveries:           "CancelPendingDeliveries",
+	opGetDelivery:                       "GetDelivery",
+	opUpdateDelivery:                    "UpdateDelivery",
+	opListDeadLetters:                   "ListDeadLetters",
+	opCreateTag:                         "CreateTag",
+	opGetTag:                            "GetTag",
+	opGetTagByName:                      "GetTagByName",
+	opListTags:                          "ListTags",
+	opListTagsByOrgID:                   "ListTagsByOrgID",
+	opUpdateTag:                         "UpdateTag",
+	opDeleteTag:                         "DeleteTag",
+	opAddMessageTag:                     "AddMessageTag",
+	opRemoveMessageTag:                  "RemoveMessageTag",
+	opGetMessageTags:                    "GetMessageTags",
+	opListMessagesByTag:                 "ListMessagesByTag",
+	opDeleteMessageTags:                 "DeleteMessageTags",
+	opCreateOrganization:                "CreateOrganization",
+	opGetOrganization:                   "GetOrganization",
+	opSaveOrgMember:                     "SaveOrgMember",
+	opGetOrgMember:                      "GetOrgMember",
+	opListOrgMembers:                    "ListOrgMembers",
+	opListOrgMembershipsByUserID:        "ListOrgMembershipsByUserID",
+	opDeleteOrgMember:                   "DeleteOrgMember",
+	opSaveOrgInvite:                     "SaveOrgInvite",
+	opGetOrgInvite:                      "GetOrgInvite",
+	opDeleteOrgInvite:                   "DeleteOrgInvite",
+	opSaveDistributionList:              "SaveDistributionList",
+	opGetDistributionList:               "GetDistributionList",
+	opGetDistributionListByAddress:      "GetDistributionListByAddress",
+	opListDistributionListsByUserID:     "ListDistributionListsByUserID",
+	opListDistributionListsByOrgID:      "ListDistributionListsByOrgID",
+	opDeleteDistributionList:            "DeleteDistributionList",
+	opSaveDistributionListDelivery:      "SaveDistributionListDelivery",
+	opListDistributionListDeliveries:    "ListDistributionListDeliveries",
+	opSaveMessageRedaction:              "SaveMessageRedaction",
+	opGetMessageRedaction:               "GetMessageRedaction",
+	opSaveAbuseReport:                   "SaveAbuseReport",
+	opGetAbuseReport:                    "GetAbuseReport",
+	opListAbuseReports:                  "ListAbuseReports",
+	opSaveMessageShare:                  "SaveMessageShare",
+	opGetMessageShare:                   "GetMessageShare",
+	opListMessageShares:                 "ListMessageShares",
+	opRecordMessageShareView:            "RecordMessageShareView",
+	opSaveSentMessage:                   "SaveSentMessage",
+	opListSentMessages:                  "ListSentMessages",
+	opCountSentMessages:                 "CountSentMessages",
+	opAddDomainWhitelistEntry:           "AddDomainWhitelistEntry",
+	opListDomainWhitelist:               "ListDomainWhitelist",
+	opDeleteDomainWhitelistEntry:        "DeleteDomainWhitelistEntry",
+	opIsLocalPartWhitelisted:            "IsLocalPartWhitelisted",
+	opSaveMailboxForward:                "SaveMailboxForward",
+	opGetMailboxForward:                 "GetMailboxForward",
+	opListMailboxForwards:               "ListMailboxForwards",
+	opDeleteMailboxForward:              "DeleteMailboxForward",
+	opSaveForwardDelivery:               "SaveForwardDelivery",
+	opListPendingForwardDeliveries:      "ListPendingForwardDeliveries",
+	opIndexMessages:                     "IndexMessages",
+	opSearchIndex:                       "SearchIndex",
+	opClearSearchIndex:                  "ClearSearchIndex",
+	opCreateMaintenanceJob:              "CreateMaintenanceJob",
+	opGetMaintenanceJob:                 "GetMaintenanceJob",
+	opListMaintenanceJobs:               "ListMaintenanceJobs",
+	opUpdateMaintenanceJob:              "UpdateMaintenanceJob",
+	opCountMessages:                     "CountMessages",
+	opListMessagesAfter:                 "ListMessagesAfter",
+	opSetMessageDerivedText:             "SetMessageDerivedText",
+	opCountMailboxes:                    "CountMailboxes",
+	opListMailboxIDsAfter:               "ListMailboxIDsAfter",
+	opRecountMailbox:                    "RecountMailbox",
+	opMergeAnalyticsBuckets:             "MergeAnalyticsBuckets",
+	opListAnalyticsBuckets:              "ListAnalyticsBuckets",
+	opDeleteAnalyticsBucketsBefore:      "DeleteAnalyticsBucketsBefore",
+	opMergeMailFlowCounters:             "MergeMailFlowCounters",
+	opListMailFlowCounters:              "ListMailFlowCounters",
+	opDeleteMailFlowCountersBefore:      "DeleteMailFlowCountersBefore",
+	opListMessagesPage:                  "ListMessagesPage",
+	opCreateReservedPrefix:              "CreateReservedPrefix",
+	opListReservedPrefixes:              "ListReservedPrefixes",
+	opFindReservedPrefixes:              "FindReservedPrefixes",
+	opDeleteReservedPrefix:              "DeleteReservedPrefix",
+	opCreateOAuthIdentity:               "CreateOAuthIdentity",
+	opGetOAuthIdentity:                  "GetOAuthIdentity",
+	opTouchOAuthIdentity:                "TouchOAuthIdentity",
+	opListOAuthIdentities:               "ListOAuthIdentities",
+	opCreateUserSession:                 "CreateUserSession",
+	opGetUserSession:                    "GetUserSession",
+	opRotateUserSession:                 "RotateUserSession",
+	opListUserSessions:                  "ListUserSessions",
+	opDeleteUserSession:                 "DeleteUserSession",
+	opDeleteExpiredUserSessions:         "DeleteExpiredUserSessions",
+	opCreateAuditLog:                    "CreateAuditLog",
+	opListAuditLogs:                     "ListAuditLogs",
+	opSaveMessagesWithOutbox:            "SaveMessagesWithOutbox",
+	opClaimOutboxEvents:                 "ClaimOutboxEvents",
+	opUpdateOutboxEvent:                 "UpdateOutboxEvent",
+	opDeleteProcessedOutboxEvents:       "DeleteProcessedOutboxEvents",
+	opRecordSinkMessage:                 "RecordSinkMessage",
+	opRecordSinkSample:                  "RecordSinkSample",
+	opGetSinkStats:                      "GetSinkStats",
+	opGetSystemConfig:                   "GetSystemConfig",
+	opSaveSystemConfig:                  "SaveSystemConfig",
+	opAddToBlacklist:                    "AddToBlacklist",
+	opIsBlacklisted:                     "IsBlacklisted",
+	opIncrementRateLimit:                "IncrementRateLimit",
+	opGetRateLimit:                      "GetRateLimit",
+	opResetRateLimit:                    "ResetRateLimit",
+	opCacheSession:                      "CacheSession",
+	opGetCachedSession:                  "GetCachedSession",
+	opDeleteCachedSession:               "DeleteCachedSession",
+	opPublishNewMail:                    "PublishNewMail",
+	opSubscribeNewMail:                  "SubscribeNewMail",
+	opClose:                             "Close",
+	opHealth:                            "Health",
+	opListAllUserDomains:                "ListAllUserDomains",
+}
+
+// Operations 返回存储层全部操作名（按下标排列）
+func Operations() []string {
+	return operations[:]
+}
+
+// Store 为存储调用计时的包装器
+//
+// 只做透传：返回值和错误原样返回（errors.Is 判断不受影响），业务层无需感知。
+type Store struct {
+	inner    storage.Store
+	observer *Observer
+}
+
+var _ storage.Store = (*Store)(nil)
+
+// NewStore 包装存储实现，backend 为指标中的后端标签（memory/postgres/hybrid 等）
+func NewStore(inner storage.Store, backend string, recorder *Recorder) *Store {
+	return &Store{inner: inner, observer: recorder.Observer(backend, operations[:])}
+}
+
+// Unwrap 返回被包装的存储实现
+func (s *Store) Unwrap() storage.Store {
+	return s.inner
+}
+
+// ========== Mailbox Repository ==========
+
+func (s *Store) CreateMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	ctx, start := s.observer.Start(ctx, opCreateMailbox)
+	err := s.inner.CreateMailbox(ctx, mailbox)
+	s.observer.End(ctx, opCreateMailbox, start, err, "")
+	return err
+}
+
+func (s *Store) SaveMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	ctx, start := s.observer.Start(ctx, opSaveMailbox)
+	err := s.inner.SaveMailbox(ctx, mailbox)
+	s.observer.End(ctx, opSaveMailbox, start, err, "")
+	return err
+}
+
+func (s *Store) GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error) {
+	ctx, start := s.observer.Start(ctx, opGetMailbox)
+	result, err := s.inner.GetMailbox(ctx, id)
+	s.observer.End(ctx, opGetMailbox, start, err, id)
+	return result, err
+}
+
+func (s *Store) GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error) {
+	ctx, start := s.observer.Start(ctx, opGetMailboxByAddress)
+	result, err := s.inner.GetMailboxByAddress(ctx, address)
+	s.observer.End(ctx, opGetMailboxByAddress, start, err, address)
+	return result, err
+}
+
+func (s *Store) GetMailboxesByAddresses(ctx context.Context, addresses []string) ([]domain.Mailbox, error) {
+	ctx, start := s.observer.Start(ctx, opGetMailboxesByAddresses)
+	result, err := s.inner.GetMailboxesByAddresses(ctx, addresses)
+	s.observer.End(ctx, opGetMailboxesByAddresses, start, err, "")
+	return result, err
+}
+
+func (s *Store) ListMailboxes(ctx context.Context) []domain.Mailbox {
+	ctx, start := s.observer.Start(ctx, opListMailboxes)
+	result := s.inner.ListMailboxes(ctx)
+	s.observer.End(ctx, opListMailboxes, start, nil, "")
+	return result
+}
+
+func (s *Store) ListMailboxesByUserID(ctx context.Context, userID string) []domain.Mailbox {
+	ctx, start := s.observer.Start(ctx, opListMailboxesByUserID)
+	result := s.inner.ListMailboxesByUserID(ctx, userID)
+	s.observer.End(ctx, opListMailboxesByUserID, start, nil, userID)
+	return result
+}
+
+func (s *Store) ListMailboxesByOrgID(ctx context.Context, orgID string) []domain.Mailbox {
+	ctx, start := s.observer.Start(ctx, opListMailboxesByOrgID)
+	result := s.inner.ListMailboxesByOrgID(ctx, orgID)
+	s.observer.End(ctx, opListMailboxesByOrgID, start, nil, orgID)
+	return result
+}
+
+func (s *Store) ListMailboxSummariesByUserID(ctx context.Context, userID string) ([]domain.MailboxSummary, error) {
+	ctx, start := s.observer.Start(ctx, opListMailboxSummariesByUserID)
+	result, err := s.inner.ListMailboxSummariesByUserID(ctx, userID)
+	s.observer.End(ctx, opListMailboxSummariesByUserID, start, err, userID)
+	return result, err
+}
+
+func (s *Store) ListMailboxSummariesByOrgID(ctx context.Context, orgID string) ([]domain.MailboxSummary, error) {
+	ctx, start := s.observer.Start(ctx, opListMailboxSummariesByOrgID)
+	result, err := s.inner.ListMailboxSummariesByOrgID(ctx, orgID)
+	s.observer.End(ctx, opListMailboxSummariesByOrgID, start, err, orgID)
+	return result, err
+}
+
+func (s *Store) DeleteMailbox(ctx context.Context, id string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteMailbox)
+	err := s.inner.DeleteMailbox(ctx, id)
+	s.observer.End(ctx, opDeleteMailbox, start, err, id)
+	return err
+}
+
+func (s *Store) DeleteExpiredMailboxes(ctx context.Context) (int, error) {
+	ctx, start := s.observer.Start(ctx, opDeleteExpiredMailboxes)
+	result, err := s.inner.DeleteExpiredMailboxes(ctx)
+	s.observer.End(ctx, opDeleteExpiredMailboxes, start, err, "")
+	return result, err
+}
+
+func (s *Store) ListExpiredMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error) {
+	ctx, start := s.observer.Start(ctx, opListExpiredMailboxes)
+	result, err := s.inner.ListExpiredMailboxes(ctx, now)
+	s.observer.End(ctx, opListExpiredMailboxes, start, err, "")
+	return result, err
+}
+
+func (s *Store) TouchMailbox(ctx context.Context, mailboxID string, at time.Time) error {
+	ctx, start := s.observer.Start(ctx, opTouchMailbox)
+	err := s.inner.TouchMailbox(ctx, mailboxID, at)
+	s.observer.End(ctx, opTouchMailbox, start, err, mailboxID)
+	return err
+}
+
+func (s *Store) ListIdleMailboxes(ctx context.Context, before time.Time, now time.Time) ([]domain.Mailbox, error) {
+	ctx, start := s.observer.Start(ctx, opListIdleMailboxes)
+	result, err := s.inner.ListIdleMailboxes(ctx, before, now)
+	s.observer.End(ctx, opListIdleMailboxes, start, err, "")
+	return result, err
+}
+
+func (s *Store) MarkMailboxIdle(ctx context.Context, mailboxID string, at time.Time, shortenTo *time.Time) error {
+	ctx, start := s.observer.Start(ctx, opMarkMailboxIdle)
+	err := s.inner.MarkMailboxIdle(ctx, mailboxID, at, shortenTo)
+	s.observer.End(ctx, opMarkMailboxIdle, start, err, mailboxID)
+	return err
+}
+
+func (s *Store) ExtendMailbox(ctx context.Context, mailboxID string, expiresAt time.Time) error {
+	ctx, start := s.observer.Start(ctx, opExtendMailbox)
+	err := s.inner.ExtendMailbox(ctx, mailboxID, expiresAt)
+	s.observer.End(ctx, opExtendMailbox, start, err, mailboxID)
+	return err
+}
+
+func (s *Store) ListPublicMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error) {
+	ctx, start := s.observer.Start(ctx, opListPublicMailboxes)
+	result, err := s.inner.ListPublicMailboxes(ctx, now)
+	s.observer.End(ctx, opListPublicMailboxes, start, err, "")
+	return result, err
+}
+
+// ========== Message Repository ==========
+
+func (s *Store) SaveMessage(ctx context.Context, message *domain.Message) error {
+	ctx, start := s.observer.Start(ctx, opSaveMessage)
+	err := s.inner.SaveMessage(ctx, message)
+	s.observer.End(ctx, opSaveMessage, start, err, "")
+	return err
+}
+
+func (s *Store) SaveMessages(ctx context.Context, messages []*domain.Message) error {
+	ctx, start := s.observer.Start(ctx, opSaveMessages)
+	err := s.inner.SaveMessages(ctx, messages)
+	s.observer.End(ctx, opSaveMessages, start, err, "")
+	return err
+}
+
+func (s *Store) ListMessages(ctx context.Context, mailboxID string) ([]domain.Message, error) {
+	ctx, start := s.observer.Start(ctx, opListMessages)
+	result, err := s.inner.ListMessages(ctx, mailboxID)
+	s.observer.End(ctx, opListMessages, start, err, mailboxID)
+	return result, err
+}
+
+func (s *Store) ListMessagesPage(ctx context.Context, query domain.MessageListQuery) (*domain.MessagePage, error) {
+	ctx, start := s.observer.Start(ctx, opListMessagesPage)
+	result, err := s.inner.ListMessagesPage(ctx, query)
+	s.observer.End(ctx, opListMessagesPage, start, err, query.MailboxID)
+	return result, err
+}
+
+func (s *Store) GetMessage(ctx context.Context, mailboxID string, messageID string) (*domain.Message, error) {
+	ctx, start := s.observer.Start(ctx, opGetMessage)
+	result, err := s.inner.GetMessage(ctx, mailboxID, messageID)
+	s.observer.End(ctx, opGetMessage, start, err, mailboxID)
+	return result, err
+}
+
+func (s *Store) MarkMessageRead(ctx context.Context, mailboxID string, messageID string) error {
+	ctx, start := s.observer.Start(ctx, opMarkMessageRead)
+	err := s.inner.MarkMessageRead(ctx, mailboxID, messageID)
+	s.observer.End(ctx, opMarkMessageRead, start, err, mailboxID)
+	return err
+}
+
+func (s *Store) MarkMessageUnread(ctx context.Context, mailboxID string, messageID string) error {
+	ctx, start := s.observer.Start(ctx, opMarkMessageUnread)
+	err := s.inner.MarkMessageUnread(ctx, mailboxID, messageID)
+	s.observer.End(ctx, opMarkMessageUnread, start, err, mailboxID)
+	return err
+}
+
+func (s *Store) DeleteMessage(ctx context.Context, mailboxID string, messageID string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteMessage)
+	err := s.inner.DeleteMessage(ctx, mailboxID, messageID)
+	s.observer.End(ctx, opDeleteMessage, start, err, mailboxID)
+	return err
+}
+
+func (s *Store) DeleteAllMessages(ctx context.Context, mailboxID string) (int, error) {
+	ctx, start := s.observer.Start(ctx, opDeleteAllMessages)
+	result, err := s.inner.DeleteAllMessages(ctx, mailboxID)
+	s.observer.End(ctx, opDeleteAllMessages, start, err, mailboxID)
+	return result, err
+}
+
+func (s *Store) SetMessageExpiry(ctx context.Context, mailboxID string, messageID string, expiresAt *time.Time) error {
+	ctx, start := s.observer.Start(ctx, opSetMessageExpiry)
+	err := s.inner.SetMessageExpiry(ctx, mailboxID, messageID, expiresAt)
+	s.observer.End(ctx, opSetMessageExpiry, start, err, mailboxID)
+	return err
+}
+
+func (s *Store) SetMessageQuarantined(ctx context.Context, mailboxID string, messageID string, quarantined bool) error {
+	ctx, start := s.observer.Start(ctx, opSetMessageQuarantined)
+	err := s.inner.SetMessageQuarantined(ctx, mailboxID, messageID, quarantined)
+	s.observer.End(ctx, opSetMessageQuarantined, start, err, mailboxID)
+	return err
+}
+
+func (s *Store) DeleteExpiredMessages(ctx context.Context, now time.Time) ([]domain.ExpiredMessages, error) {
+	ctx, start := s.observer.Start(ctx, opDeleteExpiredMessages)
+	result, err := s.inner.DeleteExpiredMessages(ctx, now)
+	s.observer.End(ctx, opDeleteExpiredMessages, start, err, "")
+	return result, err
+}
+
+func (s *Store) SearchMessages(ctx context.Context, criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error) {
+	ctx, start := s.observer.Start(ctx, opSearchMessages)
+	result, err := s.inner.SearchMessages(ctx, criteria)
+	s.observer.End(ctx, opSearchMessages, start, err, "")
+	return result, err
+}
+
+func (s *Store) GetMessageStats(ctx context.Context, query domain.MessageStatsQuery) (*domain.MessageStats, error) {
+	ctx, start := s.observer.Start(ctx, opGetMessageStats)
+	result, err := s.inner.GetMessageStats(ctx, query)
+	s.observer.End(ctx, opGetMessageStats, start, err, "")
+	return result, err
+}
+
+// ========== Alias Repository ==========
+
+func (s *Store) SaveAlias(ctx context.Context, alias *domain.MailboxAlias) error {
+	ctx, start := s.observer.Start(ctx, opSaveAlias)
+	err := s.inner.SaveAlias(ctx, alias)
+	s.observer.End(ctx, opSaveAlias, start, err, "")
+	return err
+}
+
+func (s *Store) GetAlias(ctx context.Context, aliasID string) (*domain.MailboxAlias, error) {
+	ctx, start := s.observer.Start(ctx, opGetAlias)
+	result, err := s.inner.GetAlias(ctx, aliasID)
+	s.observer.End(ctx, opGetAlias, start, err, aliasID)
+	return result, err
+}
+
+func (s *Store) GetAliasByAddress(ctx context.Context, address string) (*domain.MailboxAlias, error) {
+	ctx, start := s.observer.Start(ctx, opGetAliasByAddress)
+	result, err := s.inner.GetAliasByAddress(ctx, address)
+	s.observer.End(ctx, opGetAliasByAddress, start, err, address)
+	return result, err
+}
+
+func (s *Store) ListAliasesByMailboxID(ctx context.Context, mailboxID string) ([]*domain.MailboxAlias, error) {
+	ctx, start := s.observer.Start(ctx, opListAliasesByMailboxID)
+	result, err := s.inner.ListAliasesByMailboxID(ctx, mailboxID)
+	s.observer.End(ctx, opListAliasesByMailboxID, start, err, mailboxID)
+	return result, err
+}
+
+func (s *Store) DeleteAlias(ctx context.Context, aliasID string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteAlias)
+	err := s.inner.DeleteAlias(ctx, aliasID)
+	s.observer.End(ctx, opDeleteAlias, start, err, aliasID)
+	return err
+}
+
+// ========== User Repository ==========
+
+func (s *Store) CreateUser(ctx context.Context, user *domain.User) error {
+	ctx, start := s.observer.Start(ctx, opCreateUser)
+	err := s.inner.CreateUser(ctx, user)
+	s.observer.End(ctx, opCreateUser, start, err, "")
+	return err
+}
+
+func (s *Store) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	ctx, start := s.observer.Start(ctx, opGetUserByID)
+	result, err := s.inner.GetUserByID(ctx, id)
+	s.observer.End(ctx, opGetUserByID, start, err, id)
+	return result, err
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	ctx, start := s.observer.Start(ctx, opGetUserByEmail)
+	result, err := s.inner.GetUserByEmail(ctx, email)
+	s.observer.End(ctx, opGetUserByEmail, start, err, email)
+	return result, err
+}
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	ctx, start := s.observer.Start(ctx, opGetUserByUsername)
+	result, err := s.inner.GetUserByUsername(ctx, username)
+	s.observer.End(ctx, opGetUserByUsername, start, err, username)
+	return result, err
+}
+
+func (s *Store) GetUserByAPIKey(ctx context.Context, apiKey string) (*domain.User, error) {
+	ctx, start := s.observer.Start(ctx, opGetUserByAPIKey)
+	result, err := s.inner.GetUserByAPIKey(ctx, apiKey)
+	s.observer.End(ctx, opGetUserByAPIKey, start, err, apiKey)
+	return result, err
+}
+
+func (s *Store) UpdateUser(ctx context.Context, user *domain.User) error {
+	ctx, start := s.observer.Start(ctx, opUpdateUser)
+	err := s.inner.UpdateUser(ctx, user)
+	s.observer.End(ctx, opUpdateUser, start, err, "")
+	return err
+}
+
+func (s *Store) UpdateLastLogin(ctx context.Context, userID string) error {
+	ctx, start := s.observer.Start(ctx, opUpdateLastLogin)
+	err := s.inner.UpdateLastLogin(ctx, userID)
+	s.observer.End(ctx, opUpdateLastLogin, start, err, userID)
+	return err
+}
+
+// ========== Admin Repository ==========
+
+func (s *Store) ListUsers(ctx context.Context, page int, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) {
+	ctx, start := s.observer.Start(ctx, opListUsers)
+	r0, r1, err := s.inner.ListUsers(ctx, page, pageSize, search, role, tier, isActive)
+	s.observer.End(ctx, opListUsers, start, err, search)
+	return r0, r1, err
+}
+
+func (s *Store) DeleteUser(ctx context.Context, userID string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteUser)
+	err := s.inner.DeleteUser(ctx, userID)
+	s.observer.End(ctx, opDeleteUser, start, err, userID)
+	return err
+}
+
+func (s *Store) DeleteMailboxesByUserID(ctx context.Context, userID string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteMailboxesByUserID)
+	err := s.inner.DeleteMailboxesByUserID(ctx, userID)
+	s.observer.End(ctx, opDeleteMailboxesByUserID, start, err, userID)
+	return err
+}
+
+func (s *Store) GetSystemStatistics(ctx context.Context) (*domain.SystemStatistics, error) {
+	ctx, start := s.observer.Start(ctx, opGetSystemStatistics)
+	result, err := s.inner.GetSystemStatistics(ctx)
+	s.observer.End(ctx, opGetSystemStatistics, start, err, "")
+	return result, err
+}
+
+func (s *Store) GetDomainStatistics(ctx context.Context, domainName string) (int, int, error) {
+	ctx, start := s.observer.Start(ctx, opGetDomainStatistics)
+	r0, r1, err := s.inner.GetDomainStatistics(ctx, domainName)
+	s.observer.End(ctx, opGetDomainStatistics, start, err, domainName)
+	return r0, r1, err
+}
+
+// ========== User Domain Repository ==========
+
+func (s *Store) SaveUserDomain(ctx context.Context, userDomain *domain.UserDomain) error {
+	ctx, start := s.observer.Start(ctx, opSaveUserDomain)
+	err := s.inner.SaveUserDomain(ctx, userDomain)
+	s.observer.End(ctx, opSaveUserDomain, start, err, "")
+	return err
+}
+
+func (s *Store) GetUserDomain(ctx context.Context, domainID string) (*domain.UserDomain, error) {
+	ctx, start := s.observer.Start(ctx, opGetUserDomain)
+	result, err := s.inner.GetUserDomain(ctx, domainID)
+	s.observer.End(ctx, opGetUserDomain, start, err, domainID)
+	return result, err
+}
+
+func (s *Store) GetUserDomainByDomain(ctx context.Context, domainName string) (*domain.UserDomain, error) {
+	ctx, start := s.observer.Start(ctx, opGetUserDomainByDomain)
+	result, err := s.inner.GetUserDomainByDomain(ctx, domainName)
+	s.observer.End(ctx, opGetUserDomainByDomain, start, err, domainName)
+	return result, err
+}
+
+func (s *Store) ListUserDomainsByUserID(ctx context.Context, userID string) ([]*domain.UserDomain, error) {
+	ctx, start := s.observer.Start(ctx, opListUserDomainsByUserID)
+	result, err := s.inner.ListUserDomainsByUserID(ctx, userID)
+	s.observer.End(ctx, opListUserDomainsByUserID, start, err, userID)
+	return result, err
+}
+
+func (s *Store) ListUserDomainsByOrgID(ctx context.Context, orgID string) ([]*domain.UserDomain, error) {
+	ctx, start := s.observer.Start(ctx, opListUserDomainsByOrgID)
+	result, err := s.inner.ListUserDomainsByOrgID(ctx, orgID)
+	s.observer.End(ctx, opListUserDomainsByOrgID, start, err, orgID)
+	return result, err
+}
+
+func (s *Store) UpdateUserDomain(ctx context.Context, userDomain *domain.UserDomain) error {
+	ctx, start := s.observer.Start(ctx, opUpdateUserDomain)
+	err := s.inner.UpdateUserDomain(ctx, userDomain)
+	s.observer.End(ctx, opUpdateUserDomain, start, err, "")
+	return err
+}
+
+func (s *Store) DeleteUserDomain(ctx context.Context, domainID string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteUserDomain)
+	err := s.inner.DeleteUserDomain(ctx, domainID)
+	s.observer.End(ctx, opDeleteUserDomain, start, err, domainID)
+	return err
+}
+
+func (s *Store) IncrementMailboxCount(ctx context.Context, domainName string) error {
+	ctx, start := s.observer.Start(ctx, opIncrementMailboxCount)
+	err := s.inner.IncrementMailboxCount(ctx, domainName)
+	s.observer.End(ctx, opIncrementMailboxCount, start, err, domainName)
+	return err
+}
+
+func (s *Store) DecrementMailboxCount(ctx context.Context, domainName string) error {
+	ctx, start := s.observer.Start(ctx, opDecrementMailboxCount)
+	err := s.inner.DecrementMailboxCount(ctx, domainName)
+	s.observer.End(ctx, opDecrementMailboxCount, start, err, domainName)
+	return err
+}
+
+// ========== System Domain Repository ==========
+
+func (s *Store) SaveSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
+	ctx, start := s.observer.Start(ctx, opSaveSystemDomain)
+	err := s.inner.SaveSystemDomain(ctx, sysDomain)
+	s.observer.End(ctx, opSaveSystemDomain, start, err, "")
+	return err
+}
+
+func (s *Store) GetSystemDomain(ctx context.Context, domainID string) (*domain.SystemDomain, error) {
+	ctx, start := s.observer.Start(ctx, opGetSystemDomain)
+	result, err := s.inner.GetSystemDomain(ctx, domainID)
+	s.observer.End(ctx, opGetSystemDomain, start, err, domainID)
+	return result, err
+}
+
+func (s *Store) GetSystemDomainByDomain(ctx context.Context, domainName string) (*domain.SystemDomain, error) {
+	ctx, start := s.observer.Start(ctx, opGetSystemDomainByDomain)
+	result, err := s.inner.GetSystemDomainByDomain(ctx, domainName)
+	s.observer.End(ctx, opGetSystemDomainByDomain, start, err, domainName)
+	return result, err
+}
+
+func (s *Store) ListSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
+	ctx, start := s.observer.Start(ctx, opListSystemDomains)
+	result, err := s.inner.ListSystemDomains(ctx)
+	s.observer.End(ctx, opListSystemDomains, start, err, "")
+	return result, err
+}
+
+func (s *Store) ListActiveSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
+	ctx, start := s.observer.Start(ctx, opListActiveSystemDomains)
+	result, err := s.inner.ListActiveSystemDomains(ctx)
+	s.observer.End(ctx, opListActiveSystemDomains, start, err, "")
+	return result, err
+}
+
+func (s *Store) DeleteSystemDomain(ctx context.Context, domainID string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteSystemDomain)
+	err := s.inner.DeleteSystemDomain(ctx, domainID)
+	s.observer.End(ctx, opDeleteSystemDomain, start, err, domainID)
+	return err
+}
+
+func (s *Store) IncrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
+	ctx, start := s.observer.Start(ctx, opIncrementSystemDomainMailboxCount)
+	err := s.inner.IncrementSystemDomainMailboxCount(ctx, domainName)
+	s.observer.End(ctx, opIncrementSystemDomainMailboxCount, start, err, domainName)
+	return err
+}
+
+func (s *Store) DecrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
+	ctx, start := s.observer.Start(ctx, opDecrementSystemDomainMailboxCount)
+	err := s.inner.DecrementSystemDomainMailboxCount(ctx, domainName)
+	s.observer.End(ctx, opDecrementSystemDomainMailboxCount, start, err, domainName)
+	return err
+}
+
+func (s *Store) DeleteUnverifiedSystemDomains(ctx context.Context, before time.Time) (int, error) {
+	ctx, start := s.observer.Start(ctx, opDeleteUnverifiedSystemDomains)
+	result, err := s.inner.DeleteUnverifiedSystemDomains(ctx, before)
+	s.observer.End(ctx, opDeleteUnverifiedSystemDomains, start, err, "")
+	return result, err
+}
+
+// ========== APIKey Repository ==========
+
+func (s *Store) SaveAPIKey(ctx context.Context, apiKey *domain.APIKey) error {
+	ctx, start := s.observer.Start(ctx, opSaveAPIKey)
+	err := s.inner.SaveAPIKey(ctx, apiKey)
+	s.observer.End(ctx, opSaveAPIKey, start, err, "")
+	return err
+}
+
+func (s *Store) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	ctx, start := s.observer.Start(ctx, opGetAPIKey)
+	result, err := s.inner.GetAPIKey(ctx, id)
+	s.observer.End(ctx, opGetAPIKey, start, err, id)
+	return result, err
+}
+
+func (s *Store) GetAPIKeyByKey(ctx context.Context, key string) (*domain.APIKey, error) {
+	ctx, start := s.observer.Start(ctx, opGetAPIKeyByKey)
+	result, err := s.inner.GetAPIKeyByKey(ctx, key)
+	s.observer.End(ctx, opGetAPIKeyByKey, start, err, key)
+	return result, err
+}
+
+func (s *Store) ListAPIKeysByUserID(ctx context.Context, userID string) ([]*domain.APIKey, error) {
+	ctx, start := s.observer.Start(ctx, opListAPIKeysByUserID)
+	result, err := s.inner.ListAPIKeysByUserID(ctx, userID)
+	s.observer.End(ctx, opListAPIKeysByUserID, start, err, userID)
+	return result, err
+}
+
+func (s *Store) DeleteAPIKey(ctx context.Context, id string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteAPIKey)
+	err := s.inner.DeleteAPIKey(ctx, id)
+	s.observer.End(ctx, opDeleteAPIKey, start, err, id)
+	return err
+}
+
+func (s *Store) UpdateAPIKeyLastUsed(ctx context.Context, id string) error {
+	ctx, start := s.observer.Start(ctx, opUpdateAPIKeyLastUsed)
+	err := s.inner.UpdateAPIKeyLastUsed(ctx, id)
+	s.observer.End(ctx, opUpdateAPIKeyLastUsed, start, err, id)
+	return err
+}
+
+// ========== Webhook Repository ==========
+
+func (s *Store) CreateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	ctx, start := s.observer.Start(ctx, opCreateWebhook)
+	err := s.inner.CreateWebhook(ctx, webhook)
+	s.observer.End(ctx, opCreateWebhook, start, err, "")
+	return err
+}
+
+func (s *Store) GetWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
+	ctx, start := s.observer.Start(ctx, opGetWebhook)
+	result, err := s.inner.GetWebhook(ctx, id)
+	s.observer.End(ctx, opGetWebhook, start, err, id)
+	return result, err
+}
+
+func (s *Store) ListWebhooks(ctx context.Context, userID string) ([]domain.Webhook, error) {
+	ctx, start := s.observer.Start(ctx, opListWebhooks)
+	result, err := s.inner.ListWebhooks(ctx, userID)
+	s.observer.End(ctx, opListWebhooks, start, err, userID)
+	return result, err
+}
+
+func (s *Store) ListWebhooksByMailbox(ctx context.Context, mailboxID string) ([]domain.Webhook, error) {
+	ctx, start := s.observer.Start(ctx, opListWebhooksByMailbox)
+	result, err := s.inner.ListWebhooksByMailbox(ctx, mailboxID)
+	s.observer.End(ctx, opListWebhooksByMailbox, start, err, mailboxID)
+	return result, err
+}
+
+func (s *Store) ListWebhooksByOrgID(ctx context.Context, orgID string) ([]domain.Webhook, error) {
+	ctx, start := s.observer.Start(ctx, opListWebhooksByOrgID)
+	result, err := s.inner.ListWebhooksByOrgID(ctx, orgID)
+	s.observer.End(ctx, opListWebhooksByOrgID, start, err, orgID)
+	return result, err
+}
+
+func (s *Store) UpdateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	ctx, start := s.observer.Start(ctx, opUpdateWebhook)
+	err := s.inner.UpdateWebhook(ctx, webhook)
+	s.observer.End(ctx, opUpdateWebhook, start, err, "")
+	return err
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteWebhook)
+	err := s.inner.DeleteWebhook(ctx, id)
+	s.observer.End(ctx, opDeleteWebhook, start, err, id)
+	return err
+}
+
+func (s *Store) RecordDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	ctx, start := s.observer.Start(ctx, opRecordDelivery)
+	err := s.inner.RecordDelivery(ctx, delivery)
+	s.observer.End(ctx, opRecordDelivery, start, err, "")
+	return err
+}
+
+func (s *Store) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	ctx, start := s.observer.Start(ctx, opGetDeliveries)
+	result, err := s.inner.GetDeliveries(ctx, webhookID, limit)
+	s.observer.End(ctx, opGetDeliveries, start, err, webhookID)
+	return result, err
+}
+
+func (s *Store) GetPendingDeliveries(ctx context.Context, limit int) ([]domain.WebhookDelivery, error) {
+	ctx, start := s.observer.Start(ctx, opGetPendingDeliveries)
+	result, err := s.inner.GetPendingDeliveries(ctx, limit)
+	s.observer.End(ctx, opGetPendingDeliveries, start, err, "")
+	return result, err
+}
+
+func (s *Store) CancelPendingDeliveries(ctx context.Context, mailboxID string) (int, error) {
+	ctx, start := s.observer.Start(ctx, opCancelPendingDeliveries)
+	result, err := s.inner.CancelPendingDeliveries(ctx, mailboxID)
+	s.observer.End(ctx, opCancelPendingDeliveries, start, err, mailboxID)
+	return result, err
+}
+
+func (s *Store) GetDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	ctx, start := s.observer.Start(ctx, opGetDelivery)
+	result, err := s.inner.GetDelivery(ctx, id)
+	s.observer.End(ctx, opGetDelivery, start, err, "")
+	return result, err
+}
+
+func (s *Store) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	ctx, start := s.observer.Start(ctx, opUpdateDelivery)
+	err := s.inner.UpdateDelivery(ctx, delivery)
+	s.observer.End(ctx, opUpdateDelivery, start, err, delivery.MailboxID)
+	return err
+}
+
+func (s *Store) ListDeadLetters(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	ctx, start := s.observer.Start(ctx, opListDeadLetters)
+	result, err := s.inner.ListDeadLetters(ctx, webhookID, limit)
+	s.observer.End(ctx, opListDeadLetters, start, err, webhookID)
+	return result, err
+}
+
+// ========== Tag Repository ==========
+
+func (s *Store) CreateTag(ctx context.Context, tag *domain.Tag) error {
+	ctx, start := s.observer.Start(ctx, opCreateTag)
+	err := s.inner.CreateTag(ctx, tag)
+	s.observer.End(ctx, opCreateTag, start, err, "")
+	return err
+}
+
+func (s *Store) GetTag(ctx context.Context, id string) (*domain.Tag, error) {
+	ctx, start := s.observer.Start(ctx, opGetTag)
+	result, err := s.inner.GetTag(ctx, id)
+	s.observer.End(ctx, opGetTag, start, err, id)
+	return result, err
+}
+
+func (s *Store) GetTagByName(ctx context.Context, userID string, name string) (*domain.Tag, error) {
+	ctx, start := s.observer.Start(ctx, opGetTagByName)
+	result, err := s.inner.GetTagByName(ctx, userID, name)
+	s.observer.End(ctx, opGetTagByName, start, err, userID)
+	return result, err
+}
+
+func (s *Store) ListTags(ctx context.Context, userID string) ([]domain.TagWithCount, error) {
+	ctx, start := s.observer.Start(ctx, opListTags)
+	result, err := s.inner.ListTags(ctx, userID)
+	s.observer.End(ctx, opListTags, start, err, userID)
+	return result, err
+}
+
+func (s *Store) ListTagsByOrgID(ctx context.Context, orgID string) ([]domain.TagWithCount, error) {
+	ctx, start := s.observer.Start(ctx, opListTagsByOrgID)
+	result, err := s.inner.ListTagsByOrgID(ctx, orgID)
+	s.observer.End(ctx, opListTagsByOrgID, start, err, orgID)
+	return result, err
+}
+
+func (s *Store) UpdateTag(ctx context.Context, tag *domain.Tag) error {
+	ctx, start := s.observer.Start(ctx, opUpdateTag)
+	err := s.inner.UpdateTag(ctx, tag)
+	s.observer.End(ctx, opUpdateTag, start, err, "")
+	return err
+}
+
+func (s *Store) DeleteTag(ctx context.Context, id string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteTag)
+	err := s.inner.DeleteTag(ctx, id)
+	s.observer.End(ctx, opDeleteTag, start, err, id)
+	return err
+}
+
+func (s *Store) AddMessageTag(ctx context.Context, messageID string, tagID string) error {
+	ctx, start := s.observer.Start(ctx, opAddMessageTag)
+	err := s.inner.AddMessageTag(ctx, messageID, tagID)
+	s.observer.End(ctx, opAddMessageTag, start, err, messageID)
+	return err
+}
+
+func (s *Store) RemoveMessageTag(ctx context.Context, messageID string, tagID string) error {
+	ctx, start := s.observer.Start(ctx, opRemoveMessageTag)
+	err := s.inner.RemoveMessageTag(ctx, messageID, tagID)
+	s.observer.End(ctx, opRemoveMessageTag, start, err, messageID)
+	return err
+}
+
+func (s *Store) GetMessageTags(ctx context.Context, messageID string) ([]domain.Tag, error) {
+	ctx, start := s.observer.Start(ctx, opGetMessageTags)
+	result, err := s.inner.GetMessageTags(ctx, messageID)
+	s.observer.End(ctx, opGetMessageTags, start, err, messageID)
+	return result, err
+}
+
+func (s *Store) ListMessagesByTag(ctx context.Context, tagID string) ([]domain.Message, error) {
+	ctx, start := s.observer.Start(ctx, opListMessagesByTag)
+	result, err := s.inner.ListMessagesByTag(ctx, tagID)
+	s.observer.End(ctx, opListMessagesByTag, start, err, tagID)
+	return result, err
+}
+
+func (s *Store) DeleteMessageTags(ctx context.Context, messageID string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteMessageTags)
+	err := s.inner.DeleteMessageTags(ctx, messageID)
+	s.observer.End(ctx, opDeleteMessageTags, start, err, messageID)
+	return err
+}
+
+// ========== Organization Repository ==========
+
+func (s *Store) CreateOrganization(ctx context.Context, org *domain.Organization) error {
+	ctx, start := s.observer.Start(ctx, opCreateOrganization)
+	err := s.inner.CreateOrganization(ctx, org)
+	s.observer.End(ctx, opCreateOrganization, start, err, "")
+	return err
+}
+
+func (s *Store) GetOrganization(ctx context.Context, id string) (*domain.Organization, error) {
+	ctx, start := s.observer.Start(ctx, opGetOrganization)
+	result, err := s.inner.GetOrganization(ctx, id)
+	s.observer.End(ctx, opGetOrganization, start, err, id)
+	return result, err
+}
+
+func (s *Store) SaveOrgMember(ctx context.Context, member *domain.OrgMember) error {
+	ctx, start := s.observer.Start(ctx, opSaveOrgMember)
+	err := s.inner.SaveOrgMember(ctx, member)
+	s.observer.End(ctx, opSaveOrgMember, start, err, "")
+	return err
+}
+
+func (s *Store) GetOrgMember(ctx context.Context, orgID string, userID string) (*domain.OrgMember, error) {
+	ctx, start := s.observer.Start(ctx, opGetOrgMember)
+	result, err := s.inner.GetOrgMember(ctx, orgID, userID)
+	s.observer.End(ctx, opGetOrgMember, start, err, orgID)
+	return result, err
+}
+
+func (s *Store) ListOrgMembers(ctx context.Context, orgID string) ([]*domain.OrgMember, error) {
+	ctx, start := s.observer.Start(ctx, opListOrgMembers)
+	result, err := s.inner.ListOrgMembers(ctx, orgID)
+	s.observer.End(ctx, opListOrgMembers, start, err, orgID)
+	return result, err
+}
+
+func (s *Store) ListOrgMembershipsByUserID(ctx context.Context, userID string) ([]*domain.OrgMember, error) {
+	ctx, start := s.observer.Start(ctx, opListOrgMembershipsByUserID)
+	result, err := s.inner.ListOrgMembershipsByUserID(ctx, userID)
+	s.observer.End(ctx, opListOrgMembershipsByUserID, start, err, userID)
+	return result, err
+}
+
+func (s *Store) DeleteOrgMember(ctx context.Context, orgID string, userID string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteOrgMember)
+	err := s.inner.DeleteOrgMember(ctx, orgID, userID)
+	s.observer.End(ctx, opDeleteOrgMember, start, err, orgID)
+	return err
+}
+
+func (s *Store) SaveOrgInvite(ctx context.Context, invite *domain.OrgInvite) error {
+	ctx, start := s.observer.Start(ctx, opSaveOrgInvite)
+	err := s.inner.SaveOrgInvite(ctx, invite)
+	s.observer.End(ctx, opSaveOrgInvite, start, err, "")
+	return err
+}
+
+func (s *Store) GetOrgInvite(ctx context.Context, token string) (*domain.OrgInvite, error) {
+	ctx, start := s.observer.Start(ctx, opGetOrgInvite)
+	result, err := s.inner.GetOrgInvite(ctx, token)
+	s.observer.End(ctx, opGetOrgInvite, start, err, token)
+	return result, err
+}
+
+func (s *Store) DeleteOrgInvite(ctx context.Context, token string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteOrgInvite)
+	err := s.inner.DeleteOrgInvite(ctx, token)
+	s.observer.End(ctx, opDeleteOrgInvite, start, err, token)
+	return err
+}
+
+// ========== Distribution List Repository ==========
+
+func (s *Store) SaveDistributionList(ctx context.Context, list *domain.DistributionList) error {
+	ctx, start := s.observer.Start(ctx, opSaveDistributionList)
+	err := s.inner.SaveDistributionList(ctx, list)
+	s.observer.End(ctx, opSaveDistributionList, start, err, "")
+	return err
+}
+
+func (s *Store) GetDistributionList(ctx context.Context, id string) (*domain.DistributionList, error) {
+	ctx, start := s.observer.Start(ctx, opGetDistributionList)
+	result, err := s.inner.GetDistributionList(ctx, id)
+	s.observer.End(ctx, opGetDistributionList, start, err, id)
+	return result, err
+}
+
+func (s *Store) GetDistributionListByAddress(ctx context.Context, address string) (*domain.DistributionList, error) {
+	ctx, start := s.observer.Start(ctx, opGetDistributionListByAddress)
+	result, err := s.inner.GetDistributionListByAddress(ctx, address)
+	s.observer.End(ctx, opGetDistributionListByAddress, start, err, address)
+	return result, err
+}
+
+func (s *Store) ListDistributionListsByUserID(ctx context.Context, userID string) ([]*domain.DistributionList, error) {
+	ctx, start := s.observer.Start(ctx, opListDistributionListsByUserID)
+	result, err := s.inner.ListDistributionListsByUserID(ctx, userID)
+	s.observer.End(ctx, opListDistributionListsByUserID, start, err, userID)
+	return result, err
+}
+
+func (s *Store) ListDistributionListsByOrgID(ctx context.Context, orgID string) ([]*domain.DistributionList, error) {
+	ctx, start := s.observer.Start(ctx, opListDistributionListsByOrgID)
+	result, err := s.inner.ListDistributionListsByOrgID(ctx, orgID)
+	s.observer.End(ctx, opListDistributionListsByOrgID, start, err, orgID)
+	return result, err
+}
+
+func (s *Store) DeleteDistributionList(ctx context.Context, id string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteDistributionList)
+	err := s.inner.DeleteDistributionList(ctx, id)
+	s.observer.End(ctx, opDeleteDistributionList, start, err, id)
+	return err
+}
+
+func (s *Store) SaveDistributionListDelivery(ctx context.Context, delivery *domain.DistributionListDelivery) error {
+	ctx, start := s.observer.Start(ctx, opSaveDistributionListDelivery)
+	err := s.inner.SaveDistributionListDelivery(ctx, delivery)
+	s.observer.End(ctx, opSaveDistributionListDelivery, start, err, "")
+	return err
+}
+
+func (s *Store) ListDistributionListDeliveries(ctx context.Context, listID string, limit int) ([]*domain.DistributionListDelivery, error) {
+	ctx, start := s.observer.Start(ctx, opListDistributionListDeliveries)
+	result, err := s.inner.ListDistributionListDeliveries(ctx, listID, limit)
+	s.observer.End(ctx, opListDistributionListDeliveries, start, err, listID)
+	return result, err
+}
+
+// ========== Redaction Repository ==========
+
+func (s *Store) SaveMessageRedaction(ctx context.Context, redaction *domain.MessageRedaction) error {
+	ctx, start := s.observer.Start(ctx, opSaveMessageRedaction)
+	err := s.inner.SaveMessageRedaction(ctx, redaction)
+	s.observer.End(ctx, opSaveMessageRedaction, start, err, "")
+	return err
+}
+
+func (s *Store) GetMessageRedaction(ctx context.Context, mailboxID string, messageID string) (*domain.MessageRedaction, error) {
+	ctx, start := s.observer.Start(ctx, opGetMessageRedaction)
+	result, err := s.inner.GetMessageRedaction(ctx, mailboxID, messageID)
+	s.observer.End(ctx, opGetMessageRedaction, start, err, mailboxID)
+	return result, err
+}
+
+// ========== Abuse Report Repository ==========
+
+func (s *Store) SaveAbuseReport(ctx context.Context, report *domain.AbuseReport) error {
+	ctx, start := s.observer.Start(ctx, opSaveAbuseReport)
+	err := s.inner.SaveAbuseReport(ctx, report)
+	s.observer.End(ctx, opSaveAbuseReport, start, err, report.MailboxID)
+	return err
+}
+
+func (s *Store) GetAbuseReport(ctx context.Context, id string) (*domain.AbuseReport, error) {
+	ctx, start := s.observer.Start(ctx, opGetAbuseReport)
+	result, err := s.inner.GetAbuseReport(ctx, id)
+	s.observer.End(ctx, opGetAbuseReport, start, err, "")
+	return result, err
+}
+
+func (s *Store) ListAbuseReports(ctx context.Context, filter domain.AbuseReportFilter) ([]*domain.AbuseReport, error) {
+	ctx, start := s.observer.Start(ctx, opListAbuseReports)
+	result, err := s.inner.ListAbuseReports(ctx, filter)
+	s.observer.End(ctx, opListAbuseReports, start, err, filter.MailboxID)
+	return result, err
+}
+
+// ========== Message Share Repository ==========
+
+func (s *Store) SaveMessageShare(ctx context.Context, share *domain.MessageShare) error {
+	ctx, start := s.observer.Start(ctx, opSaveMessageShare)
+	err := s.inner.SaveMessageShare(ctx, share)
+	s.observer.End(ctx, opSaveMessageShare, start, err, share.MailboxID)
+	return err
+}
+
+func (s *Store) GetMessageShare(ctx context.Context, id string) (*domain.MessageShare, error) {
+	ctx, start := s.observer.Start(ctx, opGetMessageShare)
+	result, err := s.inner.GetMessageShare(ctx, id)
+	s.observer.End(ctx, opGetMessageShare, start, err, "")
+	return result, err
+}
+
+func (s *Store) ListMessageShares(ctx context.Context, mailboxID string) ([]*domain.MessageShare, error) {
+	ctx, start := s.observer.Start(ctx, opListMessageShares)
+	result, err := s.inner.ListMessageShares(ctx, mailboxID)
+	s.observer.End(ctx, opListMessageShares, start, err, mailboxID)
+	return result, err
+}
+
+func (s *Store) RecordMessageShareView(ctx context.Context, id string, at time.Time) error {
+	ctx, start := s.observer.Start(ctx, opRecordMessageShareView)
+	err := s.inner.RecordMessageShareView(ctx, id, at)
+	s.observer.End(ctx, opRecordMessageShareView, start, err, "")
+	return err
+}
+
+func (s *Store) SaveSentMessage(ctx context.Context, message *domain.SentMessage) error {
+	ctx, start := s.observer.Start(ctx, opSaveSentMessage)
+	err := s.inner.SaveSentMessage(ctx, message)
+	s.observer.End(ctx, opSaveSentMessage, start, err, message.MailboxID)
+	return err
+}
+
+func (s *Store) ListSentMessages(ctx context.Context, mailboxID string) ([]*domain.SentMessage, error) {
+	ctx, start := s.observer.Start(ctx, opListSentMessages)
+	result, err := s.inner.ListSentMessages(ctx, mailboxID)
+	s.observer.End(ctx, opListSentMessages, start, err, mailboxID)
+	return result, err
+}
+
+func (s *Store) CountSentMessages(ctx context.Context, filter domain.SentMessageFilter) (domain.SentMessageUsage, error) {
+	ctx, start := s.observer.Start(ctx, opCountSentMessages)
+	result, err := s.inner.CountSentMessages(ctx, filter)
+	s.observer.End(ctx, opCountSentMessages, start, err, filter.MailboxID)
+	return result, err
+}
+
+// ========== Domain Whitelist Repository ==========
+
+func (s *Store) AddDomainWhitelistEntry(ctx context.Context, entry *domain.DomainWhitelistEntry) error {
+	ctx, start := s.observer.Start(ctx, opAddDomainWhitelistEntry)
+	err := s.inner.AddDomainWhitelistEntry(ctx, entry)
+	s.observer.End(ctx, opAddDomainWhitelistEntry, start, err, entry.DomainID)
+	return err
+}
+
+func (s *Store) ListDomainWhitelist(ctx context.Context, domainID string) ([]*domain.DomainWhitelistEntry, error) {
+	ctx, start := s.observer.Start(ctx, opListDomainWhitelist)
+	result, err := s.inner.ListDomainWhitelist(ctx, domainID)
+	s.observer.End(ctx, opListDomainWhitelist, start, err, domainID)
+	return result, err
+}
+
+func (s *Store) DeleteDomainWhitelistEntry(ctx context.Context, domainID, id string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteDomainWhitelistEntry)
+	err := s.inner.DeleteDomainWhitelistEntry(ctx, domainID, id)
+	s.observer.End(ctx, opDeleteDomainWhitelistEntry, start, err, domainID)
+	return err
+}
+
+func (s *Store) IsLocalPartWhitelisted(ctx context.Context, domainID, localPart string) (bool, error) {
+	ctx, start := s.observer.Start(ctx, opIsLocalPartWhitelisted)
+	result, err := s.inner.IsLocalPartWhitelisted(ctx, domainID, localPart)
+	s.observer.End(ctx, opIsLocalPartWhitelisted, start, err, domainID)
+	return result, err
+}
+
+// ========== Reserved Prefix Repository ==========
+
+func (s *Store) CreateReservedPrefix(ctx context.Context, prefix *domain.ReservedPrefix) error {
+	ctx, start := s.observer.Start(ctx, opCreateReservedPrefix)
+	err := s.inner.CreateReservedPrefix(ctx, prefix)
+	s.observer.End(ctx, opCreateReservedPrefix, start, err, prefix.Domain)
+	return err
+}
+
+func (s *Store) ListReservedPrefixes(ctx context.Context, userID *string) ([]*domain.ReservedPrefix, error) {
+	ctx, start := s.observer.Start(ctx, opListReservedPrefixes)
+	result, err := s.inner.ListReservedPrefixes(ctx, userID)
+	s.observer.End(ctx, opListReservedPrefixes, start, err, "")
+	return result, err
+}
+
+func (s *Store) FindReservedPrefixes(ctx context.Context, domainName, localPart string) ([]*domain.ReservedPrefix, error) {
+	ctx, start := s.observer.Start(ctx, opFindReservedPrefixes)
+	result, err := s.inner.FindReservedPrefixes(ctx, domainName, localPart)
+	s.observer.End(ctx, opFindReservedPrefixes, start, err, domainName)
+	return result, err
+}
+
+func (s *Store) DeleteReservedPrefix(ctx context.Context, id string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteReservedPrefix)
+	err := s.inner.DeleteReservedPrefix(ctx, id)
+	s.observer.End(ctx, opDeleteReservedPrefix, start, err, "")
+	return err
+}
+
+// ========== OAuth Identity Repository ==========
+
+func (s *Store) CreateOAuthIdentity(ctx context.Context, identity *domain.OAuthIdentity) error {
+	ctx, start := s.observer.Start(ctx, opCreateOAuthIdentity)
+	err := s.inner.CreateOAuthIdentity(ctx, identity)
+	s.observer.End(ctx, opCreateOAuthIdentity, start, err, identity.Provider)
+	return err
+}
+
+func (s *Store) GetOAuthIdentity(ctx context.Context, provider, subject string) (*domain.OAuthIdentity, error) {
+	ctx, start := s.observer.Start(ctx, opGetOAuthIdentity)
+	result, err := s.inner.GetOAuthIdentity(ctx, provider, subject)
+	s.observer.End(ctx, opGetOAuthIdentity, start, err, provider)
+	return result, err
+}
+
+func (s *Store) TouchOAuthIdentity(ctx context.Context, id string, at time.Time) error {
+	ctx, start := s.observer.Start(ctx, opTouchOAuthIdentity)
+	err := s.inner.TouchOAuthIdentity(ctx, id, at)
+	s.observer.End(ctx, opTouchOAuthIdentity, start, err, "")
+	return err
+}
+
+func (s *Store) ListOAuthIdentities(ctx context.Context, userID string) ([]*domain.OAuthIdentity, error) {
+	ctx, start := s.observer.Start(ctx, opListOAuthIdentities)
+	result, err := s.inner.ListOAuthIdentities(ctx, userID)
+	s.observer.End(ctx, opListOAuthIdentities, start, err, userID)
+	return result, err
+}
+
+// ========== User Session Repository ==========
+
+func (s *Store) CreateUserSession(ctx context.Context, session *domain.UserSession) error {
+	ctx, start := s.observer.Start(ctx, opCreateUserSession)
+	err := s.inner.CreateUserSession(ctx, session)
+	s.observer.End(ctx, opCreateUserSession, start, err, session.UserID)
+	return err
+}
+
+func (s *Store) GetUserSession(ctx context.Context, id string) (*domain.UserSession, error) {
+	ctx, start := s.observer.Start(ctx, opGetUserSession)
+	result, err := s.inner.GetUserSession(ctx, id)
+	s.observer.End(ctx, opGetUserSession, start, err, id)
+	return result, err
+}
+
+func (s *Store) RotateUserSession(ctx context.Context, session *domain.UserSession, previousRefreshID string) error {
+	ctx, start := s.observer.Start(ctx, opRotateUserSession)
+	err := s.inner.RotateUserSession(ctx, session, previousRefreshID)
+	s.observer.End(ctx, opRotateUserSession, start, err, session.ID)
+	return err
+}
+
+func (s *Store) ListUserSessions(ctx context.Context, userID string) ([]*domain.UserSession, error) {
+	ctx, start := s.observer.Start(ctx, opListUserSessions)
+	result, err := s.inner.ListUserSessions(ctx, userID)
+	s.observer.End(ctx, opListUserSessions, start, err, userID)
+	return result, err
+}
+
+func (s *Store) DeleteUserSession(ctx context.Context, id string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteUserSession)
+	err := s.inner.DeleteUserSession(ctx, id)
+	s.observer.End(ctx, opDeleteUserSession, start, err, id)
+	return err
+}
+
+func (s *Store) DeleteExpiredUserSessions(ctx context.Context, now time.Time) (int64, error) {
+	ctx, start := s.observer.Start(ctx, opDeleteExpiredUserSessions)
+	result, err := s.inner.DeleteExpiredUserSessions(ctx, now)
+	s.observer.End(ctx, opDeleteExpiredUserSessions, start, err, "")
+	return result, err
+}
+
+// ========== Audit Log Repository ==========
+
+func (s *Store) CreateAuditLog(ctx context.Context, log *domain.AuditLog) error {
+	ctx, start := s.observer.Start(ctx, opCreateAuditLog)
+	err := s.inner.CreateAuditLog(ctx, log)
+	s.observer.End(ctx, opCreateAuditLog, start, err, log.ActorID)
+	return err
+}
+
+func (s *Store) ListAuditLogs(ctx context.Context, filter domain.AuditLogFilter) ([]*domain.AuditLog, int64, error) {
+	ctx, start := s.observer.Start(ctx, opListAuditLogs)
+	result, total, err := s.inner.ListAuditLogs(ctx, filter)
+	s.observer.End(ctx, opListAuditLogs, start, err, filter.TargetID)
+	return result, total, err
+}
+
+// ========== Outbox Repository ==========
+
+func (s *Store) SaveMessagesWithOutbox(ctx context.Context, messages []*domain.Message, events []*domain.OutboxEvent) error {
+	ctx, start := s.observer.Start(ctx, opSaveMessagesWithOutbox)
+	err := s.inner.SaveMessagesWithOutbox(ctx, messages, events)
+	s.observer.End(ctx, opSaveMessagesWithOutbox, start, err, "")
+	return err
+}
+
+func (s *Store) ClaimOutboxEvents(ctx context.Context, now, until time.Time, limit int) ([]*domain.OutboxEvent, error) {
+	ctx, start := s.observer.Start(ctx, opClaimOutboxEvents)
+	result, err := s.inner.ClaimOutboxEvents(ctx, now, until, limit)
+	s.observer.End(ctx, opClaimOutboxEvents, start, err, "")
+	return result, err
+}
+
+func (s *Store) UpdateOutboxEvent(ctx context.Context, event *domain.OutboxEvent) error {
+	ctx, start := s.observer.Start(ctx, opUpdateOutboxEvent)
+	err := s.inner.UpdateOutboxEvent(ctx, event)
+	s.observer.End(ctx, opUpdateOutboxEvent, start, err, event.ID)
+	return err
+}
+
+func (s *Store) DeleteProcessedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	ctx, start := s.observer.Start(ctx, opDeleteProcessedOutboxEvents)
+	result, err := s.inner.DeleteProcessedOutboxEvents(ctx, before)
+	s.observer.End(ctx, opDeleteProcessedOutboxEvents, start, err, "")
+	return result, err
+}
+
+// ========== Forward Repository ==========
+
+func (s *Store) SaveMailboxForward(ctx context.Context, forward *domain.MailboxForward) error {
+	ctx, start := s.observer.Start(ctx, opSaveMailboxForward)
+	err := s.inner.SaveMailboxForward(ctx, forward)
+	s.observer.End(ctx, opSaveMailboxForward, start, err, forward.MailboxID)
+	return err
+}
+
+func (s *Store) GetMailboxForward(ctx context.Context, mailboxID, id string) (*domain.MailboxForward, error) {
+	ctx, start := s.observer.Start(ctx, opGetMailboxForward)
+	result, err := s.inner.GetMailboxForward(ctx, mailboxID, id)
+	s.observer.End(ctx, opGetMailboxForward, start, err, mailboxID)
+	return result, err
+}
+
+func (s *Store) ListMailboxForwards(ctx context.Context, mailboxID string) ([]*domain.MailboxForward, error) {
+	ctx, start := s.observer.Start(ctx, opListMailboxForwards)
+	result, err := s.inner.ListMailboxForwards(ctx, mailboxID)
+	s.observer.End(ctx, opListMailboxForwards, start, err, mailboxID)
+	return result, err
+}
+
+func (s *Store) DeleteMailboxForward(ctx context.Context, mailboxID, id string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteMailboxForward)
+	err := s.inner.DeleteMailboxForward(ctx, mailboxID, id)
+	s.observer.End(ctx, opDeleteMailboxForward, start, err, mailboxID)
+	return err
+}
+
+func (s *Store) SaveForwardDelivery(ctx context.Context, delivery *domain.ForwardDelivery) error {
+	ctx, start := s.observer.Start(ctx, opSaveForwardDelivery)
+	err := s.inner.SaveForwardDelivery(ctx, delivery)
+	s.observer.End(ctx, opSaveForwardDelivery, start, err, delivery.MailboxID)
+	return err
+}
+
+func (s *Store) ListPendingForwardDeliveries(ctx context.Context, now time.Time, limit int) ([]*domain.ForwardDelivery, error) {
+	ctx, start := s.observer.Start(ctx, opListPendingForwardDeliveries)
+	result, err := s.inner.ListPendingForwardDeliveries(ctx, now, limit)
+	s.observer.End(ctx, opListPendingForwardDeliveries, start, err, "")
+	return result, err
+}
+
+// ========== Search Index Repository ==========
+
+func (s *Store) IndexMessages(ctx context.Context, docs []*domain.SearchDocument) error {
+	ctx, start := s.observer.Start(ctx, opIndexMessages)
+	err := s.inner.IndexMessages(ctx, docs)
+	s.observer.End(ctx, opIndexMessages, start, err, "")
+	return err
+}
+
+func (s *Store) SearchIndex(ctx context.Context, query domain.IndexQuery) (*domain.MessageSearchResult, error) {
+	ctx, start := s.observer.Start(ctx, opSearchIndex)
+	result, err := s.inner.SearchIndex(ctx, query)
+	s.observer.End(ctx, opSearchIndex, start, err, "")
+	return result, err
+}
+
+func (s *Store) ClearSearchIndex(ctx context.Context) error {
+	ctx, start := s.observer.Start(ctx, opClearSearchIndex)
+	err := s.inner.ClearSearchIndex(ctx)
+	s.observer.End(ctx, opClearSearchIndex, start, err, "")
+	return err
+}
+
+// ========== Maintenance Job Repository ==========
+
+func (s *Store) CreateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error {
+	ctx, start := s.observer.Start(ctx, opCreateMaintenanceJob)
+	err := s.inner.CreateMaintenanceJob(ctx, job)
+	s.observer.End(ctx, opCreateMaintenanceJob, start, err, "")
+	return err
+}
+
+func (s *Store) GetMaintenanceJob(ctx context.Context, id string) (*domain.MaintenanceJob, error) {
+	ctx, start := s.observer.Start(ctx, opGetMaintenanceJob)
+	result, err := s.inner.GetMaintenanceJob(ctx, id)
+	s.observer.End(ctx, opGetMaintenanceJob, start, err, "")
+	return result, err
+}
+
+func (s *Store) ListMaintenanceJobs(ctx context.Context) ([]*domain.MaintenanceJob, error) {
+	ctx, start := s.observer.Start(ctx, opListMaintenanceJobs)
+	result, err := s.inner.ListMaintenanceJobs(ctx)
+	s.observer.End(ctx, opListMaintenanceJobs, start, err, "")
+	return result, err
+}
+
+func (s *Store) UpdateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error {
+	ctx, start := s.observer.Start(ctx, opUpdateMaintenanceJob)
+	err := s.inner.UpdateMaintenanceJob(ctx, job)
+	s.observer.End(ctx, opUpdateMaintenanceJob, start, err, "")
+	return err
+}
+
+// ========== Maintenance Scan ==========
+
+func (s *Store) CountMessages(ctx context.Context) (int64, error) {
+	ctx, start := s.observer.Start(ctx, opCountMessages)
+	result, err := s.inner.CountMessages(ctx)
+	s.observer.End(ctx, opCountMessages, start, err, "")
+	return result, err
+}
+
+func (s *Store) ListMessagesAfter(ctx context.Context, afterID string, limit int) ([]domain.Message, error) {
+	ctx, start := s.observer.Start(ctx, opListMessagesAfter)
+	result, err := s.inner.ListMessagesAfter(ctx, afterID, limit)
+	s.observer.End(ctx, opListMessagesAfter, start, err, "")
+	return result, err
+}
+
+func (s *Store) SetMessageDerivedText(ctx context.Context, mailboxID, messageID, text string) error {
+	ctx, start := s.observer.Start(ctx, opSetMessageDerivedText)
+	err := s.inner.SetMessageDerivedText(ctx, mailboxID, messageID, text)
+	s.observer.End(ctx, opSetMessageDerivedText, start, err, mailboxID)
+	return err
+}
+
+func (s *Store) CountMailboxes(ctx context.Context) (int64, error) {
+	ctx, start := s.observer.Start(ctx, opCountMailboxes)
+	result, err := s.inner.CountMailboxes(ctx)
+	s.observer.End(ctx, opCountMailboxes, start, err, "")
+	return result, err
+}
+
+func (s *Store) ListMailboxIDsAfter(ctx context.Context, afterID string, limit int) ([]string, error) {
+	ctx, start := s.observer.Start(ctx, opListMailboxIDsAfter)
+	result, err := s.inner.ListMailboxIDsAfter(ctx, afterID, limit)
+	s.observer.End(ctx, opListMailboxIDsAfter, start, err, "")
+	return result, err
+}
+
+func (s *Store) RecountMailbox(ctx context.Context, mailboxID string) (bool, error) {
+	ctx, start := s.observer.Start(ctx, opRecountMailbox)
+	result, err := s.inner.RecountMailbox(ctx, mailboxID)
+	s.observer.End(ctx, opRecountMailbox, start, err, mailboxID)
+	return result, err
+}
+
+// ========== Analytics Repository ==========
+
+func (s *Store) MergeAnalyticsBuckets(ctx context.Context, buckets []domain.AnalyticsBucket) error {
+	ctx, start := s.observer.Start(ctx, opMergeAnalyticsBuckets)
+	err := s.inner.MergeAnalyticsBuckets(ctx, buckets)
+	s.observer.End(ctx, opMergeAnalyticsBuckets, start, err, "")
+	return err
+}
+
+func (s *Store) ListAnalyticsBuckets(ctx context.Context, metric string, since, until time.Time) ([]domain.AnalyticsBucket, error) {
+	ctx, start := s.observer.Start(ctx, opListAnalyticsBuckets)
+	result, err := s.inner.ListAnalyticsBuckets(ctx, metric, since, until)
+	s.observer.End(ctx, opListAnalyticsBuckets, start, err, "")
+	return result, err
+}
+
+func (s *Store) DeleteAnalyticsBucketsBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, start := s.observer.Start(ctx, opDeleteAnalyticsBucketsBefore)
+	result, err := s.inner.DeleteAnalyticsBucketsBefore(ctx, before)
+	s.observer.End(ctx, opDeleteAnalyticsBucketsBefore, start, err, "")
+	return result, err
+}
+
+// ========== Mail Flow Repository ==========
+
+func (s *Store) MergeMailFlowCounters(ctx context.Context, counters []domain.MailFlowCounter) error {
+	ctx, start := s.observer.Start(ctx, opMergeMailFlowCounters)
+	err := s.inner.MergeMailFlowCounters(ctx, counters)
+	s.observer.End(ctx, opMergeMailFlowCounters, start, err, "")
+	return err
+}
+
+func (s *Store) ListMailFlowCounters(ctx context.Context, since, until time.Time) ([]domain.MailFlowCounter, error) {
+	ctx, start := s.observer.Start(ctx, opListMailFlowCounters)
+	result, err := s.inner.ListMailFlowCounters(ctx, since, until)
+	s.observer.End(ctx, opListMailFlowCounters, start, err, "")
+	return result, err
+}
+
+func (s *Store) DeleteMailFlowCountersBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, start := s.observer.Start(ctx, opDeleteMailFlowCountersBefore)
+	result, err := s.inner.DeleteMailFlowCountersBefore(ctx, before)
+	s.observer.End(ctx, opDeleteMailFlowCountersBefore, start, err, "")
+	return result, err
+}
+
+// ========== Sink Stats Repository ==========
+
+func (s *Store) RecordSinkMessage(ctx context.Context, domainName string, sender string, size int64, at time.Time) (int64, error) {
+	ctx, start := s.observer.Start(ctx, opRecordSinkMessage)
+	result, err := s.inner.RecordSinkMessage(ctx, domainName, sender, size, at)
+	s.observer.End(ctx, opRecordSinkMessage, start, err, domainName)
+	return result, err
+}
+
+func (s *Store) RecordSinkSample(ctx context.Context, domainName string) error {
+	ctx, start := s.observer.Start(ctx, opRecordSinkSample)
+	err := s.inner.RecordSinkSample(ctx, domainName)
+	s.observer.End(ctx, opRecordSinkSample, start, err, domainName)
+	return err
+}
+
+func (s *Store) GetSinkStats(ctx context.Context, domainName string) (*domain.SinkStats, error) {
+	ctx, start := s.observer.Start(ctx, opGetSinkStats)
+	result, err := s.inner.GetSinkStats(ctx, domainName)
+	s.observer.End(ctx, opGetSinkStats, start, err, domainName)
+	return result, err
+}
+
+// ========== System Config Repository ==========
+
+func (s *Store) GetSystemConfig(ctx context.Context) (*domain.SystemConfig, error) {
+	ctx, start := s.observer.Start(ctx, opGetSystemConfig)
+	result, err := s.inner.GetSystemConfig(ctx)
+	s.observer.End(ctx, opGetSystemConfig, start, err, "")
+	return result, err
+}
+
+func (s *Store) SaveSystemConfig(ctx context.Context, config *domain.SystemConfig) error {
+	ctx, start := s.observer.Start(ctx, opSaveSystemConfig)
+	err := s.inner.SaveSystemConfig(ctx, config)
+	s.observer.End(ctx, opSaveSystemConfig, start, err, "")
+	return err
+}
+
+// ========== JWTRepository ==========
+
+func (s *Store) AddToBlacklist(ctx context.Context, jti string, ttl time.Duration) error {
+	ctx, start := s.observer.Start(ctx, opAddToBlacklist)
+	err := s.inner.AddToBlacklist(ctx, jti, ttl)
+	s.observer.End(ctx, opAddToBlacklist, start, err, jti)
+	return err
+}
+
+func (s *Store) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
+	ctx, start := s.observer.Start(ctx, opIsBlacklisted)
+	result, err := s.inner.IsBlacklisted(ctx, jti)
+	s.observer.End(ctx, opIsBlacklisted, start, err, jti)
+	return result, err
+}
+
+// ========== Rate Limit Repository ==========
+
+func (s *Store) IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error) {
+	ctx, start := s.observer.Start(ctx, opIncrementRateLimit)
+	result, err := s.inner.IncrementRateLimit(ctx, key, window)
+	s.observer.End(ctx, opIncrementRateLimit, start, err, key)
+	return result, err
+}
+
+func (s *Store) GetRateLimit(ctx context.Context, key string) (int64, error) {
+	ctx, start := s.observer.Start(ctx, opGetRateLimit)
+	result, err := s.inner.GetRateLimit(ctx, key)
+	s.observer.End(ctx, opGetRateLimit, start, err, key)
+	return result, err
+}
+
+func (s *Store) ResetRateLimit(ctx context.Context, key string) error {
+	ctx, start := s.observer.Start(ctx, opResetRateLimit)
+	err := s.inner.ResetRateLimit(ctx, key)
+	s.observer.End(ctx, opResetRateLimit, start, err, key)
+	return err
+}
+
+// ========== Session Repository ==========
+
+func (s *Store) CacheSession(ctx context.Context, sessionID string, userID string, ttl time.Duration) error {
+	ctx, start := s.observer.Start(ctx, opCacheSession)
+	err := s.inner.CacheSession(ctx, sessionID, userID, ttl)
+	s.observer.End(ctx, opCacheSession, start, err, sessionID)
+	return err
+}
+
+func (s *Store) GetCachedSession(ctx context.Context, sessionID string) (string, error) {
+	ctx, start := s.observer.Start(ctx, opGetCachedSession)
+	result, err := s.inner.GetCachedSession(ctx, sessionID)
+	s.observer.End(ctx, opGetCachedSession, start, err, sessionID)
+	return result, err
+}
+
+func (s *Store) DeleteCachedSession(ctx context.Context, sessionID string) error {
+	ctx, start := s.observer.Start(ctx, opDeleteCachedSession)
+	err := s.inner.DeleteCachedSession(ctx, sessionID)
+	s.observer.End(ctx, opDeleteCachedSession, start, err, sessionID)
+	return err
+}
+
+// ========== Pub Sub Repository ==========
+
+func (s *Store) PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error {
+	ctx, start := s.observer.Start(ctx, opPublishNewMail)
+	err := s.inner.PublishNewMail(ctx, mailboxID, message)
+	s.observer.End(ctx, opPublishNewMail, start, err, mailboxID)
+	return err
+}
+
+func (s *Store) SubscribeNewMail(ctx context.Context) (<-chan *domain.Message, error) {
+	ctx, start := s.observer.Start(ctx, opSubscribeNewMail)
+	result, err := s.inner.SubscribeNewMail(ctx)
+	s.observer.End(ctx, opSubscribeNewMail, start, err, "")
+	return result, err
+}
+
+// ========== Utility ==========
+
+func (s *Store) Close() error {
+	start := time.Now()
+	err := s.inner.Close()
+	s.observer.Observe(opClose, start, err, "")
+	return err
+}
+
+func (s *Store) Health() error {
+	start := time.Now()
+	err := s.inner.Health()
+	s.observer.Observe(opHealth, start, err, "")
+	return err
+}
+
+func (s *Store) ListAllUserDomains(ctx context.Context) ([]*domain.UserDomain, error) {
+	ctx, start := s.observer.Start(ctx, opListAllUserDomains)
+	result, err := s.inner.ListAllUserDomains(ctx)
+	s.observer.End(ctx, opListAllUserDomains, start, err, "")
+	return result, err
+}
@@ -0,0 +1,149 @@
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// slowStore 指定方法人为变慢的存储
+type slowStore struct {
+	storage.Store
+	delay time.Duration
+}
+
+func (s *slowStore) GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error) {
+	time.Sleep(s.delay)
+	return s.Store.GetMailboxByAddress(ctx, address)
+}
+
+func (s *slowStore) GetOrgInvite(ctx context.Context, token string) (*domain.OrgInvite, error) {
+	time.Sleep(s.delay)
+	return s.Store.GetOrgInvite(ctx, token)
+}
+
+// histogramCount 读取指定标签的直方图样本数
+func histogramCount(t *testing.T, reg *prometheus.Registry, labels map[string]string) uint64 {
+	t.Helper()
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "tempmail_store_operation_duration_seconds" {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				if labels[pair.GetName()] != pair.GetValue() {
+					continue metrics
+				}
+			}
+			return metric.GetHistogram().GetSampleCount()
+		}
+	}
+	return 0
+}
+
+func TestStore(t *testing.T) {
+	inner := memory.NewStore(24 * time.Hour)
+	mailbox := &domain.Mailbox{ID: "mb-1", Address: "alice@corp.example", LocalPart: "alice", Domain: "corp.example", Token: "tok", CreatedAt: time.Now()}
+	require.NoError(t, inner.SaveMailbox(t.Context(), mailbox))
+
+	t.Run("按方法和后端记录耗时", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		store := NewStore(inner, "memory", NewRecorder(reg, time.Hour, 0))
+
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{ID: "msg-1", MailboxID: "mb-1", ReceivedAt: time.Now()}))
+		assert.EqualValues(t, 1, histogramCount(t, reg, map[string]string{"method": "SaveMessage", "backend": "memory", "status": "ok"}))
+
+		assert.Error(t, store.SaveMessage(t.Context(), &domain.Message{ID: "msg-2", MailboxID: "missing"}))
+		assert.EqualValues(t, 1, histogramCount(t, reg, map[string]string{"method": "SaveMessage", "backend": "memory", "status": "error"}))
+	})
+
+	t.Run("返回值与错误原样透传", func(t *testing.T) {
+		store := NewStore(inner, "memory", NewRecorder(prometheus.NewRegistry(), time.Hour, 0))
+
+		got, err := store.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+		want, err := inner.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+
+		_, err = store.GetMailbox(t.Context(), "missing")
+		assert.True(t, errors.Is(err, storage.ErrMailboxNotFound))
+		_, err = store.GetOrgInvite(t.Context(), "missing")
+		assert.True(t, errors.Is(err, storage.ErrOrgInviteNotFound))
+		assert.Len(t, store.ListMailboxes(t.Context()), len(inner.ListMailboxes(t.Context())))
+	})
+
+	t.Run("慢调用记入列表且参数已脱敏", func(t *testing.T) {
+		recorder := NewRecorder(prometheus.NewRegistry(), 10*time.Millisecond, 0)
+		store := NewStore(&slowStore{Store: inner, delay: 15 * time.Millisecond}, "postgres", recorder)
+
+		_, err := store.GetMailboxByAddress(t.Context(), "alice@corp.example")
+		require.NoError(t, err)
+		_, _ = store.GetOrgInvite(t.Context(), "invite-token-0123456789abcdef")
+		_, err = store.GetMailbox(t.Context(), "mb-1") // 未超过阈值，不记录
+		require.NoError(t, err)
+
+		calls := recorder.SlowCalls()
+		require.Len(t, calls, 2)
+		byMethod := map[string]SlowCall{}
+		for _, call := range calls {
+			assert.Equal(t, "postgres", call.Backend)
+			assert.GreaterOrEqual(t, call.Duration, 10*time.Millisecond)
+			byMethod[call.Method] = call
+		}
+		assert.Equal(t, "a***@corp.example", byMethod["GetMailboxByAddress"].Key)
+		assert.Equal(t, "invite-t…", byMethod["GetOrgInvite"].Key)
+		assert.True(t, byMethod["GetOrgInvite"].Failed)
+	})
+
+	t.Run("每次调用创建请求 span 的子 span", func(t *testing.T) {
+		spans := tracetest.NewSpanRecorder()
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+		t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+		store := NewStore(inner, "postgres", NewRecorder(prometheus.NewRegistry(), time.Hour, 0))
+		ctx, parent := otel.Tracer("test").Start(t.Context(), "request")
+		_, err := store.GetMailbox(ctx, "mb-1")
+		require.NoError(t, err)
+		_, err = store.GetOrgInvite(ctx, "missing")
+		require.Error(t, err)
+		parent.End()
+
+		ended := spans.Ended()
+		require.Len(t, ended, 3)
+		assert.Equal(t, "store.GetMailbox", ended[0].Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), ended[0].Parent().SpanID())
+		assert.Contains(t, ended[0].Attributes(), attribute.String("store.backend", "postgres"))
+		assert.Equal(t, codes.Unset, ended[0].Status().Code)
+		assert.Equal(t, "store.GetOrgInvite", ended[1].Name())
+		assert.Equal(t, codes.Error, ended[1].Status().Code)
+	})
+
+	t.Run("只保留最慢的 N 条", func(t *testing.T) {
+		recorder := NewRecorder(prometheus.NewRegistry(), 0, 3)
+		for _, ms := range []int{5, 1, 9, 3, 7} {
+			recorder.RecordSlowQuery("SELECT 1", time.Duration(ms)*time.Millisecond, nil)
+		}
+		calls := recorder.SlowCalls()
+		require.Len(t, calls, 3)
+		assert.Equal(t, []float64{9, 7, 5}, []float64{calls[0].Millis, calls[1].Millis, calls[2].Millis})
+	})
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// SaveAbuseReport 保存滥用举报（覆盖已有记录）
+func (s *Store) SaveAbuseReport(ctx context.Context, report *domain.AbuseReport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *report
+	s.abuseReports[report.ID] = &copied
+	return nil
+}
+
+// GetAbuseReport 获取滥用举报
+func (s *Store) GetAbuseReport(ctx context.Context, id string) (*domain.AbuseReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	report, ok := s.abuseReports[id]
+	if !ok {
+		return nil, storage.ErrAbuseReportNotFound
+	}
+	copied := *report
+	return &copied, nil
+}
+
+// ListAbuseReports 按过滤条件列出举报（按创建时间倒序）
+func (s *Store) ListAbuseReports(ctx context.Context, filter domain.AbuseReportFilter) ([]*domain.AbuseReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var reports []*domain.AbuseReport
+	for _, report := range s.abuseReports {
+		if filter.Matches(report) {
+			copied := *report
+			reports = append(reports, &copied)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].CreatedAt.Equal(reports[j].CreatedAt) {
+			return reports[i].CreatedAt.After(reports[j].CreatedAt)
+		}
+		return reports[i].ID > reports[j].ID
+	})
+	return reports, nil
+}
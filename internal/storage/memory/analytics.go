@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// analyticsKey 统计时间桶的主键（时间桶以 UTC 纳秒保存，保证同一时刻只有一个键）
+type analyticsKey struct {
+	metric    string
+	dimension string
+	bucket    int64
+}
+
+func newAnalyticsKey(bucket domain.AnalyticsBucket) analyticsKey {
+	return analyticsKey{metric: bucket.Metric, dimension: bucket.Dimension, bucket: bucket.Bucket.UnixNano()}
+}
+
+// MergeAnalyticsBuckets 合并统计计数：峰值类指标取最大值，其余累加
+func (s *Store) MergeAnalyticsBuckets(ctx context.Context, buckets []domain.AnalyticsBucket) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, bucket := range buckets {
+		key := newAnalyticsKey(bucket)
+		if domain.IsPeakAnalyticsMetric(bucket.Metric) {
+			s.analytics[key] = max(s.analytics[key], bucket.Value)
+		} else {
+			s.analytics[key] += bucket.Value
+		}
+	}
+	return nil
+}
+
+// ListAnalyticsBuckets 列出指标在 [since, until) 内的时间桶
+func (s *Store) ListAnalyticsBuckets(ctx context.Context, metric string, since, until time.Time) ([]domain.AnalyticsBucket, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	from, to := since.UnixNano(), until.UnixNano()
+	return s.analyticsBucketsLocked(func(key analyticsKey) bool {
+		return key.metric == metric && key.bucket >= from && key.bucket < to
+	}), nil
+}
+
+// DeleteAnalyticsBucketsBefore 删除早于 before 的时间桶
+func (s *Store) DeleteAnalyticsBucketsBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	cutoff := before.UnixNano()
+	for key := range s.analytics {
+		if key.bucket < cutoff {
+			delete(s.analytics, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// analyticsBucketsLocked 按时间、指标、维度排序返回匹配的时间桶，调用方需持有锁
+func (s *Store) analyticsBucketsLocked(match func(analyticsKey) bool) []domain.AnalyticsBucket {
+	var result []domain.AnalyticsBucket
+	for key, value := range s.analytics {
+		if !match(key) {
+			continue
+		}
+		result = append(result, domain.AnalyticsBucket{
+			Metric:    key.metric,
+			Dimension: key.dimension,
+			Bucket:    time.Unix(0, key.bucket).UTC(),
+			Value:     value,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !a.Bucket.Equal(b.Bucket) {
+			return a.Bucket.Before(b.Bucket)
+		}
+		if a.Metric != b.Metric {
+			return a.Metric < b.Metric
+		}
+		return a.Dimension < b.Dimension
+	})
+	return result
+}
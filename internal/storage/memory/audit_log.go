@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tempmail/backend/internal/domain"
+)
+
+// CreateAuditLog 追加审计记录
+func (s *Store) CreateAuditLog(ctx context.Context, log *domain.AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *log
+	s.auditLogs = append(s.auditLogs, &copied)
+	return nil
+}
+
+// ListAuditLogs 按过滤条件分页列出审计记录（按时间倒序）
+func (s *Store) ListAuditLogs(ctx context.Context, filter domain.AuditLogFilter) ([]*domain.AuditLog, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*domain.AuditLog
+	for _, log := range s.auditLogs {
+		if filter.Matches(log) {
+			matched = append(matched, log)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+
+	total := int64(len(matched))
+	start := min(filter.Offset, len(matched))
+	end := len(matched)
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, end)
+	}
+	logs := make([]*domain.AuditLog, 0, end-start)
+	for _, log := range matched[start:end] {
+		copied := *log
+		logs = append(logs, &copied)
+	}
+	return logs, total, nil
+}
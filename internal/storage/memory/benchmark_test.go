@@ -23,7 +23,7 @@ func BenchmarkMemoryStore_SaveMailbox(b *testing.B) {
 			Token:     fmt.Sprintf("token-%d", i),
 			CreatedAt: time.Now(),
 		}
-		store.SaveMailbox(mailbox)
+		store.SaveMailbox(b.Context(), mailbox)
 	}
 }
 
@@ -42,13 +42,13 @@ func BenchmarkMemoryStore_GetMailbox(b *testing.B) {
 			Token:     fmt.Sprintf("token-%d", i),
 			CreatedAt: time.Now(),
 		}
-		store.SaveMailbox(mailbox)
+		store.SaveMailbox(b.Context(), mailbox)
 	}
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		id := fmt.Sprintf("mailbox-%d", i%1000)
-		store.GetMailbox(id)
+		store.GetMailbox(b.Context(), id)
 	}
 }
 
@@ -66,7 +66,7 @@ func BenchmarkMemoryStore_SaveMessage(b *testing.B) {
 		Token:     "test-token",
 		CreatedAt: time.Now(),
 	}
-	store.SaveMailbox(mailbox)
+	store.SaveMailbox(b.Context(), mailbox)
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -79,7 +79,7 @@ func BenchmarkMemoryStore_SaveMessage(b *testing.B) {
 			Text:      "This is a test message body",
 			CreatedAt: time.Now(),
 		}
-		store.SaveMessage(message)
+		store.SaveMessage(b.Context(), message)
 	}
 }
 
@@ -99,8 +99,8 @@ func BenchmarkMemoryStore_ConcurrentAccess(b *testing.B) {
 				Token:     fmt.Sprintf("token-%d", i),
 				CreatedAt: time.Now(),
 			}
-			store.SaveMailbox(mailbox)
-			store.GetMailbox(mailbox.ID)
+			store.SaveMailbox(b.Context(), mailbox)
+			store.GetMailbox(b.Context(), mailbox.ID)
 			i++
 		}
 	})
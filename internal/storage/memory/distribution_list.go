@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// SaveDistributionList 保存分发列表（地址唯一）
+func (s *Store) SaveDistributionList(ctx context.Context, list *domain.DistributionList) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existingID, ok := s.listsByAddress[list.Address]; ok && existingID != list.ID {
+		return storage.ErrDistributionListExists
+	}
+	if previous, ok := s.lists[list.ID]; ok && previous.Address != list.Address {
+		delete(s.listsByAddress, previous.Address)
+	}
+	copied := *list
+	copied.Members = append([]string(nil), list.Members...)
+	s.lists[list.ID] = &copied
+	s.listsByAddress[list.Address] = list.ID
+	return nil
+}
+
+// GetDistributionList 获取分发列表
+func (s *Store) GetDistributionList(ctx context.Context, id string) (*domain.DistributionList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list, ok := s.lists[id]
+	if !ok {
+		return nil, storage.ErrDistributionListNotFound
+	}
+	return copyDistributionList(list), nil
+}
+
+// GetDistributionListByAddress 根据地址获取分发列表
+func (s *Store) GetDistributionListByAddress(ctx context.Context, address string) (*domain.DistributionList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.listsByAddress[address]
+	if !ok {
+		return nil, storage.ErrDistributionListNotFound
+	}
+	return copyDistributionList(s.lists[id]), nil
+}
+
+// ListDistributionListsByUserID 列出用户创建的分发列表
+func (s *Store) ListDistributionListsByUserID(ctx context.Context, userID string) ([]*domain.DistributionList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.filterDistributionListsLocked(func(list *domain.DistributionList) bool {
+		return list.UserID == userID
+	}), nil
+}
+
+// ListDistributionListsByOrgID 列出组织的分发列表
+func (s *Store) ListDistributionListsByOrgID(ctx context.Context, orgID string) ([]*domain.DistributionList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.filterDistributionListsLocked(func(list *domain.DistributionList) bool {
+		return list.OrgID != nil && *list.OrgID == orgID
+	}), nil
+}
+
+// DeleteDistributionList 删除分发列表及其投递报告
+func (s *Store) DeleteDistributionList(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, ok := s.lists[id]
+	if !ok {
+		return storage.ErrDistributionListNotFound
+	}
+	delete(s.listsByAddress, list.Address)
+	delete(s.lists, id)
+	delete(s.listDeliveries, id)
+	return nil
+}
+
+// SaveDistributionListDelivery 记录分发列表投递报告
+func (s *Store) SaveDistributionListDelivery(ctx context.Context, delivery *domain.DistributionListDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.lists[delivery.ListID]; !ok {
+		return storage.ErrDistributionListNotFound
+	}
+	s.listDeliveries[delivery.ListID] = append(s.listDeliveries[delivery.ListID], delivery)
+	return nil
+}
+
+// ListDistributionListDeliveries 列出分发列表最近的投递报告（新的在前）
+func (s *Store) ListDistributionListDeliveries(ctx context.Context, listID string, limit int) ([]*domain.DistributionListDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := s.listDeliveries[listID]
+	result := make([]*domain.DistributionListDelivery, 0, len(deliveries))
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		result = append(result, deliveries[i])
+	}
+	return result, nil
+}
+
+// filterDistributionListsLocked 按条件筛选分发列表（按创建时间排序），调用方需持有锁
+func (s *Store) filterDistributionListsLocked(match func(*domain.DistributionList) bool) []*domain.DistributionList {
+	result := make([]*domain.DistributionList, 0)
+	for _, list := range s.lists {
+		if match(list) {
+			result = append(result, copyDistributionList(list))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
+
+func copyDistributionList(list *domain.DistributionList) *domain.DistributionList {
+	copied := *list
+	copied.Members = append([]string(nil), list.Members...)
+	return &copied
+}
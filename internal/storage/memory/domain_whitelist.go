@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// AddDomainWhitelistEntry 添加白名单条目
+func (s *Store) AddDomainWhitelistEntry(ctx context.Context, entry *domain.DomainWhitelistEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.userDomains[entry.DomainID]; !ok {
+		return ErrUserDomainNotFound
+	}
+	for _, existing := range s.domainWhitelist {
+		if existing.DomainID == entry.DomainID && existing.LocalPart == entry.LocalPart {
+			return storage.ErrWhitelistEntryExists
+		}
+	}
+	copied := *entry
+	s.domainWhitelist[entry.ID] = &copied
+	return nil
+}
+
+// ListDomainWhitelist 列出域名的白名单（按前缀排序）
+func (s *Store) ListDomainWhitelist(ctx context.Context, domainID string) ([]*domain.DomainWhitelistEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []*domain.DomainWhitelistEntry
+	for _, entry := range s.domainWhitelist {
+		if entry.DomainID == domainID {
+			copied := *entry
+			entries = append(entries, &copied)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LocalPart < entries[j].LocalPart })
+	return entries, nil
+}
+
+// DeleteDomainWhitelistEntry 删除域名下的白名单条目
+func (s *Store) DeleteDomainWhitelistEntry(ctx context.Context, domainID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.domainWhitelist[id]
+	if !ok || entry.DomainID != domainID {
+		return storage.ErrWhitelistEntryNotFound
+	}
+	delete(s.domainWhitelist, id)
+	return nil
+}
+
+// IsLocalPartWhitelisted 前缀是否在域名白名单中
+func (s *Store) IsLocalPartWhitelisted(ctx context.Context, domainID, localPart string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, entry := range s.domainWhitelist {
+		if entry.DomainID == domainID && entry.LocalPart == localPart {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// SaveMailboxForward 保存转发地址（新建或更新）
+func (s *Store) SaveMailboxForward(ctx context.Context, forward *domain.MailboxForward) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.mailboxes[forward.MailboxID]; !ok {
+		return ErrMailboxNotFound
+	}
+	copied := *forward
+	s.mailboxForwards[forward.ID] = &copied
+	return nil
+}
+
+// GetMailboxForward 获取邮箱下的转发地址
+func (s *Store) GetMailboxForward(ctx context.Context, mailboxID, id string) (*domain.MailboxForward, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	forward, ok := s.mailboxForwards[id]
+	if !ok || forward.MailboxID != mailboxID {
+		return nil, storage.ErrForwardNotFound
+	}
+	copied := *forward
+	return &copied, nil
+}
+
+// ListMailboxForwards 列出邮箱的转发地址（按创建时间排序）
+func (s *Store) ListMailboxForwards(ctx context.Context, mailboxID string) ([]*domain.MailboxForward, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var forwards []*domain.MailboxForward
+	for _, forward := range s.mailboxForwards {
+		if forward.MailboxID == mailboxID {
+			copied := *forward
+			forwards = append(forwards, &copied)
+		}
+	}
+	sort.Slice(forwards, func(i, j int) bool {
+		if !forwards[i].CreatedAt.Equal(forwards[j].CreatedAt) {
+			return forwards[i].CreatedAt.Before(forwards[j].CreatedAt)
+		}
+		return forwards[i].ID < forwards[j].ID
+	})
+	return forwards, nil
+}
+
+// DeleteMailboxForward 删除邮箱下的转发地址及其投递记录
+func (s *Store) DeleteMailboxForward(ctx context.Context, mailboxID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	forward, ok := s.mailboxForwards[id]
+	if !ok || forward.MailboxID != mailboxID {
+		return storage.ErrForwardNotFound
+	}
+	delete(s.mailboxForwards, id)
+	for deliveryID, delivery := range s.forwardDeliveries {
+		if delivery.ForwardID == id {
+			delete(s.forwardDeliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// SaveForwardDelivery 保存投递记录（新建或更新）
+func (s *Store) SaveForwardDelivery(ctx context.Context, delivery *domain.ForwardDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *delivery
+	if copied.NextRetry != nil {
+		next := *copied.NextRetry
+		copied.NextRetry = &next
+	}
+	s.forwardDeliveries[delivery.ID] = &copied
+	return nil
+}
+
+// ListPendingForwardDeliveries 列出到期待投递的记录（按 NextRetry 排序）
+func (s *Store) ListPendingForwardDeliveries(ctx context.Context, now time.Time, limit int) ([]*domain.ForwardDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var deliveries []*domain.ForwardDelivery
+	for _, delivery := range s.forwardDeliveries {
+		if delivery.Success || delivery.NextRetry == nil || delivery.NextRetry.After(now) {
+			continue
+		}
+		copied := *delivery
+		next := *delivery.NextRetry
+		copied.NextRetry = &next
+		deliveries = append(deliveries, &copied)
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].NextRetry.Before(*deliveries[j].NextRetry) })
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
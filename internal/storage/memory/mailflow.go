@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// mailFlowKey 收信流量计数的主键（时间桶以 UTC 纳秒保存）
+type mailFlowKey struct {
+	kind      string
+	dimension string
+	bucket    int64
+}
+
+// MergeMailFlowCounters 累加收信流量计数
+func (s *Store) MergeMailFlowCounters(ctx context.Context, counters []domain.MailFlowCounter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, counter := range counters {
+		s.mailFlow[mailFlowKey{kind: counter.Kind, dimension: counter.Dimension, bucket: counter.Bucket.UnixNano()}] += counter.Count
+	}
+	return nil
+}
+
+// ListMailFlowCounters 列出 [since, until) 内的收信流量计数
+func (s *Store) ListMailFlowCounters(ctx context.Context, since, until time.Time) ([]domain.MailFlowCounter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	from, to := since.UnixNano(), until.UnixNano()
+	var result []domain.MailFlowCounter
+	for key, count := range s.mailFlow {
+		if key.bucket < from || key.bucket >= to {
+			continue
+		}
+		result = append(result, domain.MailFlowCounter{
+			Kind:      key.kind,
+			Dimension: key.dimension,
+			Bucket:    time.Unix(0, key.bucket).UTC(),
+			Count:     count,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !a.Bucket.Equal(b.Bucket) {
+			return a.Bucket.Before(b.Bucket)
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Dimension < b.Dimension
+	})
+	return result, nil
+}
+
+// DeleteMailFlowCountersBefore 删除早于 before 的收信流量计数
+func (s *Store) DeleteMailFlowCountersBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	cutoff := before.UnixNano()
+	for key := range s.mailFlow {
+		if key.bucket < cutoff {
+			delete(s.mailFlow, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
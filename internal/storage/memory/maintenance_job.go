@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// CreateMaintenanceJob 创建维护任务，同类型已有排队或运行中的任务时拒绝
+func (s *Store) CreateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.SyncActiveType()
+	if job.ActiveType != nil && s.activeMaintenanceJobLocked(job.Type, job.ID) {
+		return storage.ErrMaintenanceJobActive
+	}
+	copied := *job
+	s.maintenanceJobs[job.ID] = &copied
+	return nil
+}
+
+// GetMaintenanceJob 获取维护任务
+func (s *Store) GetMaintenanceJob(ctx context.Context, id string) (*domain.MaintenanceJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.maintenanceJobs[id]
+	if !ok {
+		return nil, storage.ErrMaintenanceJobNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// ListMaintenanceJobs 列出维护任务（按创建时间倒序）
+func (s *Store) ListMaintenanceJobs(ctx context.Context) ([]*domain.MaintenanceJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]*domain.MaintenanceJob, 0, len(s.maintenanceJobs))
+	for _, job := range s.maintenanceJobs {
+		copied := *job
+		jobs = append(jobs, &copied)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID > jobs[j].ID
+	})
+	return jobs, nil
+}
+
+// UpdateMaintenanceJob 保存维护任务状态和进度
+func (s *Store) UpdateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.maintenanceJobs[job.ID]; !ok {
+		return storage.ErrMaintenanceJobNotFound
+	}
+	job.SyncActiveType()
+	if job.ActiveType != nil && s.activeMaintenanceJobLocked(job.Type, job.ID) {
+		return storage.ErrMaintenanceJobActive
+	}
+	copied := *job
+	s.maintenanceJobs[job.ID] = &copied
+	return nil
+}
+
+// activeMaintenanceJobLocked 是否有其他同类型的任务排队或运行中（调用方持有锁）
+func (s *Store) activeMaintenanceJobLocked(jobType domain.MaintenanceJobType, exceptID string) bool {
+	for id, job := range s.maintenanceJobs {
+		if id != exceptID && job.Type == jobType && job.Active() {
+			return true
+		}
+	}
+	return false
+}
+
+// CountMessages 统计全部邮件数
+func (s *Store) CountMessages(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int64
+	for _, msgMap := range s.messages {
+		count += int64(len(msgMap))
+	}
+	return count, nil
+}
+
+// ListMessagesAfter 按 ID 升序列出 ID 大于 afterID 的邮件
+func (s *Store) ListMessagesAfter(ctx context.Context, afterID string, limit int) ([]domain.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []domain.Message
+	for _, msgMap := range s.messages {
+		for id, msg := range msgMap {
+			if id > afterID {
+				result = append(result, *msg)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// SetMessageDerivedText 保存由 HTML 生成的纯文本（内存存储直接保存在邮件上）
+func (s *Store) SetMessageDerivedText(ctx context.Context, mailboxID, messageID, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.messages[mailboxID][messageID]
+	if !ok {
+		return ErrMessageNotFound
+	}
+	msg.Text = text
+	msg.HasText = true
+	msg.TextDerivedFromHTML = true
+	return nil
+}
+
+// CountMailboxes 统计全部邮箱数
+func (s *Store) CountMailboxes(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.mailboxes)), nil
+}
+
+// ListMailboxIDsAfter 按 ID 升序列出 ID 大于 afterID 的邮箱 ID
+func (s *Store) ListMailboxIDsAfter(ctx context.Context, afterID string, limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []string
+	for id := range s.mailboxes {
+		if id > afterID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+// RecountMailbox 按邮件重新统计邮箱的总数和未读数（邮箱已删除时视为没有变化）
+func (s *Store) RecountMailbox(ctx context.Context, mailboxID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mb, ok := s.mailboxes[mailboxID]
+	if !ok {
+		return false, nil
+	}
+	total, unread := 0, 0
+	for _, msg := range s.messages[mailboxID] {
+		total++
+		if !msg.IsRead {
+			unread++
+		}
+	}
+	if mb.TotalCount == total && mb.Unread == unread {
+		return false, nil
+	}
+	mb.TotalCount, mb.Unread = total, unread
+	return true, nil
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// SaveMessageShare 保存邮件分享链接（覆盖已有记录）
+func (s *Store) SaveMessageShare(ctx context.Context, share *domain.MessageShare) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *share
+	s.messageShares[share.ID] = &copied
+	return nil
+}
+
+// GetMessageShare 获取邮件分享链接
+func (s *Store) GetMessageShare(ctx context.Context, id string) (*domain.MessageShare, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	share, ok := s.messageShares[id]
+	if !ok {
+		return nil, storage.ErrMessageShareNotFound
+	}
+	copied := *share
+	return &copied, nil
+}
+
+// ListMessageShares 列出邮箱的分享链接（按创建时间倒序）
+func (s *Store) ListMessageShares(ctx context.Context, mailboxID string) ([]*domain.MessageShare, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var shares []*domain.MessageShare
+	for _, share := range s.messageShares {
+		if share.MailboxID == mailboxID {
+			copied := *share
+			shares = append(shares, &copied)
+		}
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if !shares[i].CreatedAt.Equal(shares[j].CreatedAt) {
+			return shares[i].CreatedAt.After(shares[j].CreatedAt)
+		}
+		return shares[i].ID > shares[j].ID
+	})
+	return shares, nil
+}
+
+// RecordMessageShareView 访问次数加一并记录访问时间
+func (s *Store) RecordMessageShareView(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	share, ok := s.messageShares[id]
+	if !ok {
+		return storage.ErrMessageShareNotFound
+	}
+	share.Views++
+	share.LastViewedAt = &at
+	return nil
+}
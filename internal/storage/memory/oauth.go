@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// CreateOAuthIdentity 关联第三方登录身份
+func (s *Store) CreateOAuthIdentity(ctx context.Context, identity *domain.OAuthIdentity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.oauthIdentities {
+		if existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+			return storage.ErrOAuthIdentityExists
+		}
+	}
+	s.oauthIdentities[identity.ID] = copyOAuthIdentity(identity)
+	return nil
+}
+
+// GetOAuthIdentity 按提供方和提供方用户标识查找
+func (s *Store) GetOAuthIdentity(ctx context.Context, provider, subject string) (*domain.OAuthIdentity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, identity := range s.oauthIdentities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return copyOAuthIdentity(identity), nil
+		}
+	}
+	return nil, storage.ErrOAuthIdentityNotFound
+}
+
+// TouchOAuthIdentity 更新身份的最近登录时间
+func (s *Store) TouchOAuthIdentity(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, ok := s.oauthIdentities[id]
+	if !ok {
+		return storage.ErrOAuthIdentityNotFound
+	}
+	identity.LastLoginAt = &at
+	return nil
+}
+
+// ListOAuthIdentities 列出用户关联的第三方登录身份（按关联时间排序）
+func (s *Store) ListOAuthIdentities(ctx context.Context, userID string) ([]*domain.OAuthIdentity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var identities []*domain.OAuthIdentity
+	for _, identity := range s.oauthIdentities {
+		if identity.UserID == userID {
+			identities = append(identities, copyOAuthIdentity(identity))
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].CreatedAt.Before(identities[j].CreatedAt)
+	})
+	return identities, nil
+}
+
+func copyOAuthIdentity(identity *domain.OAuthIdentity) *domain.OAuthIdentity {
+	copied := *identity
+	if identity.LastLoginAt != nil {
+		lastLoginAt := *identity.LastLoginAt
+		copied.LastLoginAt = &lastLoginAt
+	}
+	return &copied
+}
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// CreateOrganization 创建组织
+func (s *Store) CreateOrganization(ctx context.Context, org *domain.Organization) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.orgs[org.ID] = org
+	return nil
+}
+
+// GetOrganization 获取组织
+func (s *Store) GetOrganization(ctx context.Context, id string) (*domain.Organization, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	org, ok := s.orgs[id]
+	if !ok {
+		return nil, storage.ErrOrganizationNotFound
+	}
+	return org, nil
+}
+
+// SaveOrgMember 保存组织成员（已存在时更新角色）
+func (s *Store) SaveOrgMember(ctx context.Context, member *domain.OrgMember) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.orgs[member.OrgID]; !ok {
+		return storage.ErrOrganizationNotFound
+	}
+	if s.orgMembers[member.OrgID] == nil {
+		s.orgMembers[member.OrgID] = make(map[string]*domain.OrgMember)
+	}
+	s.orgMembers[member.OrgID][member.UserID] = member
+	return nil
+}
+
+// GetOrgMember 获取组织成员
+func (s *Store) GetOrgMember(ctx context.Context, orgID, userID string) (*domain.OrgMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	member, ok := s.orgMembers[orgID][userID]
+	if !ok {
+		return nil, storage.ErrOrgMemberNotFound
+	}
+	copied := *member
+	return &copied, nil
+}
+
+// ListOrgMembers 列出组织的所有成员
+func (s *Store) ListOrgMembers(ctx context.Context, orgID string) ([]*domain.OrgMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*domain.OrgMember, 0, len(s.orgMembers[orgID]))
+	for _, member := range s.orgMembers[orgID] {
+		copied := *member
+		result = append(result, &copied)
+	}
+	return result, nil
+}
+
+// ListOrgMembershipsByUserID 列出用户加入的所有组织成员关系
+func (s *Store) ListOrgMembershipsByUserID(ctx context.Context, userID string) ([]*domain.OrgMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*domain.OrgMember, 0)
+	for _, members := range s.orgMembers {
+		if member, ok := members[userID]; ok {
+			copied := *member
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+// DeleteOrgMember 移除组织成员
+func (s *Store) DeleteOrgMember(ctx context.Context, orgID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.orgMembers[orgID][userID]; !ok {
+		return storage.ErrOrgMemberNotFound
+	}
+	delete(s.orgMembers[orgID], userID)
+	return nil
+}
+
+// SaveOrgInvite 保存组织邀请
+func (s *Store) SaveOrgInvite(ctx context.Context, invite *domain.OrgInvite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.orgInvites[invite.Token] = invite
+	return nil
+}
+
+// GetOrgInvite 根据加入令牌获取邀请
+func (s *Store) GetOrgInvite(ctx context.Context, token string) (*domain.OrgInvite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invite, ok := s.orgInvites[token]
+	if !ok {
+		return nil, storage.ErrOrgInviteNotFound
+	}
+	return invite, nil
+}
+
+// DeleteOrgInvite 删除邀请（接受后作废）
+func (s *Store) DeleteOrgInvite(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.orgInvites[token]; !ok {
+		return storage.ErrOrgInviteNotFound
+	}
+	delete(s.orgInvites, token)
+	return nil
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// SaveMessagesWithOutbox 在同一把写锁内保存邮件和发件箱事件，邮件保存失败时事件也不写入
+func (s *Store) SaveMessagesWithOutbox(ctx context.Context, messages []*domain.Message, events []*domain.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.saveMessagesLocked(messages); err != nil {
+		return err
+	}
+	for _, event := range events {
+		s.outboxEvents[event.ID] = copyOutboxEvent(event)
+	}
+	return nil
+}
+
+// ClaimOutboxEvents 认领可投递的事件（按创建时间排序）
+func (s *Store) ClaimOutboxEvents(ctx context.Context, now, until time.Time, limit int) ([]*domain.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var claimable []*domain.OutboxEvent
+	for _, event := range s.outboxEvents {
+		if event.Claimable(now) {
+			claimable = append(claimable, event)
+		}
+	}
+	sort.Slice(claimable, func(i, j int) bool {
+		if !claimable[i].CreatedAt.Equal(claimable[j].CreatedAt) {
+			return claimable[i].CreatedAt.Before(claimable[j].CreatedAt)
+		}
+		return claimable[i].ID < claimable[j].ID
+	})
+	if limit > 0 && len(claimable) > limit {
+		claimable = claimable[:limit]
+	}
+
+	events := make([]*domain.OutboxEvent, 0, len(claimable))
+	for _, event := range claimable {
+		claimedUntil := until
+		event.ClaimedUntil = &claimedUntil
+		events = append(events, copyOutboxEvent(event))
+	}
+	return events, nil
+}
+
+// UpdateOutboxEvent 保存事件的投递进度
+func (s *Store) UpdateOutboxEvent(ctx context.Context, event *domain.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.outboxEvents[event.ID]
+	if !ok {
+		return nil
+	}
+	stored.Delivered = slices.Clone(event.Delivered)
+	stored.Attempts = event.Attempts
+	stored.LastError = event.LastError
+	stored.NextAttemptAt = event.NextAttemptAt
+	stored.ClaimedUntil = copyTime(event.ClaimedUntil)
+	stored.ProcessedAt = copyTime(event.ProcessedAt)
+	return nil
+}
+
+// DeleteProcessedOutboxEvents 删除早于 before 处理完成的事件
+func (s *Store) DeleteProcessedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, event := range s.outboxEvents {
+		if event.ProcessedAt != nil && event.ProcessedAt.Before(before) {
+			delete(s.outboxEvents, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// copyOutboxEvent 复制事件（含切片和时间指针），调用方修改副本不影响存储
+func copyOutboxEvent(event *domain.OutboxEvent) *domain.OutboxEvent {
+	copied := *event
+	copied.Delivered = slices.Clone(event.Delivered)
+	copied.ClaimedUntil = copyTime(event.ClaimedUntil)
+	copied.ProcessedAt = copyTime(event.ProcessedAt)
+	return &copied
+}
+
+// copyTime 复制时间指针
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// SaveMessageRedaction 保存邮件脱敏副本（覆盖已有副本）
+func (s *Store) SaveMessageRedaction(ctx context.Context, redaction *domain.MessageRedaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.messages[redaction.MailboxID][redaction.MessageID]; !ok {
+		return ErrMessageNotFound
+	}
+	s.redactions[redaction.MessageID] = copyRedaction(redaction)
+	return nil
+}
+
+// GetMessageRedaction 获取邮件脱敏副本
+func (s *Store) GetMessageRedaction(ctx context.Context, mailboxID, messageID string) (*domain.MessageRedaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	redaction, ok := s.redactions[messageID]
+	if !ok || redaction.MailboxID != mailboxID {
+		return nil, storage.ErrRedactionNotFound
+	}
+	return copyRedaction(redaction), nil
+}
+
+func copyRedaction(redaction *domain.MessageRedaction) *domain.MessageRedaction {
+	copied := *redaction
+	copied.Rules = append([]string(nil), redaction.Rules...)
+	copied.Patterns = append([]string(nil), redaction.Patterns...)
+	return &copied
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// CreateReservedPrefix 添加保留前缀
+func (s *Store) CreateReservedPrefix(ctx context.Context, prefix *domain.ReservedPrefix) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.reservedPrefixes {
+		if existing.Domain == prefix.Domain && existing.LocalPart == prefix.LocalPart {
+			return storage.ErrReservedPrefixExists
+		}
+	}
+	s.reservedPrefixes[prefix.ID] = copyReservedPrefix(prefix)
+	return nil
+}
+
+// ListReservedPrefixes 列出保留前缀（按域名、前缀排序）
+func (s *Store) ListReservedPrefixes(ctx context.Context, userID *string) ([]*domain.ReservedPrefix, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var prefixes []*domain.ReservedPrefix
+	for _, prefix := range s.reservedPrefixes {
+		if userID != nil && (prefix.UserID == nil || *prefix.UserID != *userID) {
+			continue
+		}
+		prefixes = append(prefixes, copyReservedPrefix(prefix))
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if prefixes[i].Domain != prefixes[j].Domain {
+			return prefixes[i].Domain < prefixes[j].Domain
+		}
+		return prefixes[i].LocalPart < prefixes[j].LocalPart
+	})
+	return prefixes, nil
+}
+
+// FindReservedPrefixes 查找前缀在指定域名及所有系统域名上的条目
+func (s *Store) FindReservedPrefixes(ctx context.Context, domainName, localPart string) ([]*domain.ReservedPrefix, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var prefixes []*domain.ReservedPrefix
+	for _, prefix := range s.reservedPrefixes {
+		if prefix.LocalPart == localPart && (prefix.Domain == "" || prefix.Domain == domainName) {
+			prefixes = append(prefixes, copyReservedPrefix(prefix))
+		}
+	}
+	return prefixes, nil
+}
+
+// DeleteReservedPrefix 删除保留前缀
+func (s *Store) DeleteReservedPrefix(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.reservedPrefixes[id]; !ok {
+		return storage.ErrReservedPrefixNotFound
+	}
+	delete(s.reservedPrefixes, id)
+	return nil
+}
+
+func copyReservedPrefix(prefix *domain.ReservedPrefix) *domain.ReservedPrefix {
+	copied := *prefix
+	if prefix.UserID != nil {
+		userID := *prefix.UserID
+		copied.UserID = &userID
+	}
+	if prefix.ExpiresAt != nil {
+		expiresAt := *prefix.ExpiresAt
+		copied.ExpiresAt = &expiresAt
+	}
+	return &copied
+}
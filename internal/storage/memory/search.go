@@ -1,13 +1,15 @@
 package memory
 
 import (
+	"context"
 	"strings"
 
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/htmltext"
 )
 
 // SearchMessages 搜索邮件（内存存储实现）
-func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error) {
+func (s *Store) SearchMessages(ctx context.Context, criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -42,8 +44,8 @@ func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.M
 		}
 	}
 
-	// 按时间倒序排序
-	sortByCreatedAtDesc(filtered)
+	// 按序号倒序排序
+	domain.SortMessagesBySeq(filtered)
 
 	// 分页
 	total := len(filtered)
@@ -76,7 +78,12 @@ func matchesCriteria(msg *domain.Message, criteria domain.MessageSearchCriteria)
 		query := strings.ToLower(criteria.Query)
 		subject := strings.ToLower(msg.Subject)
 		from := strings.ToLower(msg.From)
-		text := strings.ToLower(msg.Text)
+		text := msg.Text
+		if text == "" && msg.HTML != "" {
+			// 功能上线前入库的纯 HTML 邮件：临时转换，不回写
+			text = htmltext.Render(msg.HTML)
+		}
+		text = strings.ToLower(text)
 
 		if !strings.Contains(subject, query) &&
 			!strings.Contains(from, query) &&
@@ -120,18 +127,28 @@ func matchesCriteria(msg *domain.Message, criteria domain.MessageSearchCriteria)
 		}
 	}
 
-	return true
-}
+	// 语言筛选
+	if criteria.Language != "" && !strings.EqualFold(msg.DetectedLanguage, criteria.Language) {
+		return false
+	}
 
-// sortByCreatedAtDesc 按创建时间倒序排序
-func sortByCreatedAtDesc(messages []domain.Message) {
-	// 简单冒泡排序（内存存储数据量不大）
-	n := len(messages)
-	for i := 0; i < n-1; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if messages[j].CreatedAt.Before(messages[j+1].CreatedAt) {
-				messages[j], messages[j+1] = messages[j+1], messages[j]
-			}
-		}
+	// 垃圾邮件评分筛选
+	if criteria.SpamScoreGte != nil && msg.SpamScore < *criteria.SpamScoreGte {
+		return false
+	}
+	if criteria.IsSpam != nil && msg.IsSpam != *criteria.IsSpam {
+		return false
 	}
+
+	// DKIM 验证结果筛选
+	if criteria.DKIMResult != "" && msg.DKIMResult != criteria.DKIMResult {
+		return false
+	}
+
+	// 保存的头筛选
+	if !msg.MatchesHeaders(criteria.Headers) {
+		return false
+	}
+
+	return true
 }
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"tempmail/backend/internal/domain"
+)
+
+// IndexMessages 写入或覆盖邮件的索引文档
+func (s *Store) IndexMessages(ctx context.Context, docs []*domain.SearchDocument) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range docs {
+		stored := *doc
+		s.indexMessageLocked(&stored)
+	}
+	return nil
+}
+
+// SearchIndex 从最短的倒排表开始求交集，按接收时间倒序分页
+func (s *Store) SearchIndex(ctx context.Context, query domain.IndexQuery) (*domain.MessageSearchResult, error) {
+	query.Normalize()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := &domain.MessageSearchResult{Messages: []domain.Message{}, Page: query.Page, PageSize: query.PageSize}
+	if len(query.Terms) == 0 || len(query.MailboxIDs) == 0 {
+		return result, nil
+	}
+	lists := make([]map[string]struct{}, 0, len(query.Terms))
+	for _, term := range query.Terms {
+		postings := s.searchPostings[term]
+		if len(postings) == 0 {
+			return result, nil
+		}
+		lists = append(lists, postings)
+	}
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+
+	mailboxes := make(map[string]bool, len(query.MailboxIDs))
+	for _, id := range query.MailboxIDs {
+		mailboxes[id] = true
+	}
+
+	var hits []*domain.SearchDocument
+	for messageID := range lists[0] {
+		doc := s.searchDocs[messageID]
+		if !mailboxes[doc.MailboxID] {
+			continue
+		}
+		if msg, ok := s.messages[doc.MailboxID][messageID]; !ok || msg.Quarantined {
+			continue
+		}
+		matched := true
+		for _, postings := range lists[1:] {
+			if _, ok := postings[messageID]; !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			hits = append(hits, doc)
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if !hits[i].ReceivedAt.Equal(hits[j].ReceivedAt) {
+			return hits[i].ReceivedAt.After(hits[j].ReceivedAt)
+		}
+		return hits[i].MessageID > hits[j].MessageID
+	})
+
+	result.Total = len(hits)
+	result.TotalPages = (result.Total + query.PageSize - 1) / query.PageSize
+	start := (query.Page - 1) * query.PageSize
+	if start >= len(hits) {
+		return result, nil
+	}
+	end := min(start+query.PageSize, len(hits))
+	for _, doc := range hits[start:end] {
+		result.Messages = append(result.Messages, *s.messages[doc.MailboxID][doc.MessageID])
+	}
+	return result, nil
+}
+
+// ClearSearchIndex 清空索引
+func (s *Store) ClearSearchIndex(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.searchDocs = make(map[string]*domain.SearchDocument)
+	s.searchPostings = make(map[string]map[string]struct{})
+	return nil
+}
+
+// indexMessageLocked 写入索引文档并更新倒排表（调用方持有写锁）
+func (s *Store) indexMessageLocked(doc *domain.SearchDocument) {
+	s.unindexMessageLocked(doc.MessageID)
+	s.searchDocs[doc.MessageID] = doc
+	for _, term := range strings.Fields(doc.Terms) {
+		postings, ok := s.searchPostings[term]
+		if !ok {
+			postings = make(map[string]struct{})
+			s.searchPostings[term] = postings
+		}
+		postings[doc.MessageID] = struct{}{}
+	}
+}
+
+// unindexMessageLocked 删除邮件的索引文档及其倒排项（调用方持有写锁）
+func (s *Store) unindexMessageLocked(messageID string) {
+	doc, ok := s.searchDocs[messageID]
+	if !ok {
+		return
+	}
+	for _, term := range strings.Fields(doc.Terms) {
+		postings := s.searchPostings[term]
+		delete(postings, messageID)
+		if len(postings) == 0 {
+			delete(s.searchPostings, term)
+		}
+	}
+	delete(s.searchDocs, messageID)
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tempmail/backend/internal/domain"
+)
+
+// SaveSentMessage 保存已发送邮件
+func (s *Store) SaveSentMessage(ctx context.Context, message *domain.SentMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.mailboxes[message.MailboxID]; !ok {
+		return ErrMailboxNotFound
+	}
+	copied := *message
+	s.sentMessages[message.ID] = &copied
+	return nil
+}
+
+// ListSentMessages 列出邮箱发出的邮件（按发送时间倒序）
+func (s *Store) ListSentMessages(ctx context.Context, mailboxID string) ([]*domain.SentMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var messages []*domain.SentMessage
+	for _, message := range s.sentMessages {
+		if message.MailboxID == mailboxID {
+			copied := *message
+			messages = append(messages, &copied)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if !messages[i].SentAt.Equal(messages[j].SentAt) {
+			return messages[i].SentAt.After(messages[j].SentAt)
+		}
+		return messages[i].ID > messages[j].ID
+	})
+	return messages, nil
+}
+
+// CountSentMessages 按邮箱或用户统计 Since 之后发出的邮件数及其中最早的发送时间
+func (s *Store) CountSentMessages(ctx context.Context, filter domain.SentMessageFilter) (domain.SentMessageUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var usage domain.SentMessageUsage
+	for _, message := range s.sentMessages {
+		if !message.SentAt.After(filter.Since) ||
+			(filter.MailboxID != "" && message.MailboxID != filter.MailboxID) ||
+			(filter.UserID != "" && message.UserID != filter.UserID) {
+			continue
+		}
+		if usage.Count == 0 || message.SentAt.Before(usage.OldestAt) {
+			usage.OldestAt = message.SentAt
+		}
+		usage.Count++
+	}
+	return usage, nil
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// sinkSenderCap 每个域名记录的去重发件人上限，超出后不再增加（统计值为下限）
+const sinkSenderCap = 10000
+
+// sinkCounter 黑洞模式计数器（发件人只保存哈希）
+type sinkCounter struct {
+	messages int64
+	bytes    int64
+	sampled  int64
+	last     time.Time
+	senders  map[uint64]struct{}
+	hourly   map[int64]*domain.SinkHourlyCount // 整点 Unix 秒 -> 小时桶
+}
+
+// RecordSinkMessage 累计一封黑洞邮件
+func (s *Store) RecordSinkMessage(ctx context.Context, domainName, sender string, size int64, at time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter := s.sinkCounterLocked(domainName)
+	counter.messages++
+	counter.bytes += size
+	if at.After(counter.last) {
+		counter.last = at
+	}
+
+	if len(counter.senders) < sinkSenderCap {
+		sum := sha256.Sum256([]byte(strings.ToLower(sender)))
+		counter.senders[binary.BigEndian.Uint64(sum[:8])] = struct{}{}
+	}
+
+	hour := at.UTC().Truncate(time.Hour)
+	bucket, ok := counter.hourly[hour.Unix()]
+	if !ok {
+		bucket = &domain.SinkHourlyCount{Hour: hour}
+		counter.hourly[hour.Unix()] = bucket
+	}
+	bucket.Messages++
+	bucket.Bytes += size
+
+	cutoff := hour.Add(-domain.SinkStatsRetention).Unix()
+	for key := range counter.hourly {
+		if key <= cutoff {
+			delete(counter.hourly, key)
+		}
+	}
+	return counter.messages, nil
+}
+
+// RecordSinkSample 累计一封抽样邮件
+func (s *Store) RecordSinkSample(ctx context.Context, domainName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sinkCounterLocked(domainName).sampled++
+	return nil
+}
+
+// GetSinkStats 获取黑洞模式统计
+func (s *Store) GetSinkStats(ctx context.Context, domainName string) (*domain.SinkStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	domainName = strings.ToLower(domainName)
+	stats := &domain.SinkStats{Domain: domainName, Hourly: []domain.SinkHourlyCount{}}
+	counter, ok := s.sinks[domainName]
+	if !ok {
+		return stats, nil
+	}
+
+	stats.Messages = counter.messages
+	stats.Bytes = counter.bytes
+	stats.UniqueSenders = int64(len(counter.senders))
+	stats.Sampled = counter.sampled
+	if !counter.last.IsZero() {
+		last := counter.last
+		stats.LastReceivedAt = &last
+	}
+	for _, bucket := range counter.hourly {
+		stats.Hourly = append(stats.Hourly, *bucket)
+	}
+	sort.Slice(stats.Hourly, func(i, j int) bool {
+		return stats.Hourly[i].Hour.Before(stats.Hourly[j].Hour)
+	})
+	return stats, nil
+}
+
+func (s *Store) sinkCounterLocked(domainName string) *sinkCounter {
+	domainName = strings.ToLower(domainName)
+	counter, ok := s.sinks[domainName]
+	if !ok {
+		counter = &sinkCounter{
+			senders: make(map[uint64]struct{}),
+			hourly:  make(map[int64]*domain.SinkHourlyCount),
+		}
+		s.sinks[domainName] = counter
+	}
+	return counter
+}
@@ -0,0 +1,589 @@
+package memory
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// SnapshotVersion 快照格式版本，字段有不兼容变化时递增
+const SnapshotVersion = 1
+
+// ErrSnapshotVersion 快照版本不受支持
+var ErrSnapshotVersion = errors.New("unsupported snapshot version")
+
+// Snapshot 内存存储内容的快照（重启后恢复，或迁移到数据库存储）
+//
+// 投递记录、重试队列、黑洞计数和速率限制属于运行时状态，不写入快照。
+type Snapshot struct {
+	Version           int                            `json:"version"`
+	CreatedAt         time.Time                      `json:"createdAt"`
+	Users             []SnapshotUser                 `json:"users"`
+	APIKeys           []*domain.APIKey               `json:"apiKeys"`
+	SystemDomains     []*domain.SystemDomain         `json:"systemDomains"`
+	UserDomains       []*domain.UserDomain           `json:"userDomains"`
+	Mailboxes         []SnapshotMailbox              `json:"mailboxes"`
+	Aliases           []*domain.MailboxAlias         `json:"aliases"`
+	Messages          []SnapshotMessage              `json:"messages"`
+	Webhooks          []*domain.Webhook              `json:"webhooks"`
+	Tags              []*domain.Tag                  `json:"tags"`
+	MessageTags       []*domain.MessageTag           `json:"messageTags"`
+	Organizations     []*domain.Organization         `json:"organizations"`
+	OrgMembers        []*domain.OrgMember            `json:"orgMembers"`
+	OrgInvites        []*domain.OrgInvite            `json:"orgInvites"`
+	DistributionLists []*domain.DistributionList     `json:"distributionLists"`
+	Redactions        []*domain.MessageRedaction     `json:"redactions"`
+	AbuseReports      []*domain.AbuseReport          `json:"abuseReports,omitempty"`
+	MessageShares     []SnapshotMessageShare         `json:"messageShares,omitempty"`
+	SentMessages      []*domain.SentMessage          `json:"sentMessages,omitempty"`
+	DomainWhitelist   []*domain.DomainWhitelistEntry `json:"domainWhitelist,omitempty"`
+	ReservedPrefixes  []*domain.ReservedPrefix       `json:"reservedPrefixes,omitempty"`
+	OAuthIdentities   []*domain.OAuthIdentity        `json:"oauthIdentities,omitempty"`
+	UserSessions      []*domain.UserSession          `json:"userSessions,omitempty"`
+	AuditLogs         []*domain.AuditLog             `json:"auditLogs,omitempty"`
+	OutboxEvents      []*domain.OutboxEvent          `json:"outboxEvents,omitempty"`
+	MailboxForwards   []SnapshotMailboxForward       `json:"mailboxForwards,omitempty"`
+	MaintenanceJobs   []*domain.MaintenanceJob       `json:"maintenanceJobs,omitempty"`
+	AnalyticsBuckets  []domain.AnalyticsBucket       `json:"analyticsBuckets,omitempty"`
+	SystemConfig      *domain.SystemConfig           `json:"systemConfig,omitempty"`
+	RevokedTokens     map[string]time.Time           `json:"revokedTokens,omitempty"` // jti -> 过期时间
+	Sessions          []SnapshotSession              `json:"sessions,omitempty"`
+}
+
+// SnapshotUser 用户及其不对外序列化的字段
+type SnapshotUser struct {
+	*domain.User
+	PasswordHash          string   `json:"passwordHash"`
+	RecentLoginIPs        []string `json:"recentLoginIps,omitempty"`
+	TOTPSecret            string   `json:"totpSecret,omitempty"`
+	TOTPLastStep          int64    `json:"totpLastStep,omitempty"`
+	RecoveryCodes         []string `json:"recoveryCodes,omitempty"`
+	AppPasswordGeneration int      `json:"appPasswordGeneration,omitempty"`
+}
+
+// SnapshotMailbox 邮箱及其不对外序列化的字段
+type SnapshotMailbox struct {
+	*domain.Mailbox
+	IPSource              string     `json:"ipSource,omitempty"`
+	IdleShortened         bool       `json:"idleShortened,omitempty"`
+	IdleOriginalExpiresAt *time.Time `json:"idleOriginalExpiresAt,omitempty"`
+	MessageSeq            int64      `json:"messageSeq,omitempty"` // 最近分配的邮件序号
+}
+
+// SnapshotMessageShare 分享链接及其不对外序列化的 Nonce
+type SnapshotMessageShare struct {
+	*domain.MessageShare
+	Nonce string `json:"nonce"`
+}
+
+// SnapshotMailboxForward 转发地址及其不对外序列化的确认状态
+type SnapshotMailboxForward struct {
+	*domain.MailboxForward
+	CodeHash       string `json:"codeHash,omitempty"`
+	VerifyAttempts int    `json:"verifyAttempts,omitempty"`
+}
+
+// SnapshotMessage 邮件及附件内容（按附件顺序，没有内存内容的附件为空）
+type SnapshotMessage struct {
+	*domain.Message
+	AttachmentContent [][]byte `json:"attachmentContent,omitempty"`
+}
+
+// SnapshotSession 登录会话
+type SnapshotSession struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Snapshot 生成当前内容的快照（按 ID 排序，实体为浅拷贝）
+func (s *Store) Snapshot() *Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneExpiredLocked()
+
+	snap := &Snapshot{
+		Version:       SnapshotVersion,
+		CreatedAt:     time.Now().UTC(),
+		RevokedTokens: make(map[string]time.Time, len(s.blacklist)),
+	}
+
+	for _, u := range s.users {
+		user := *u
+		snap.Users = append(snap.Users, SnapshotUser{
+			User:                  &user,
+			PasswordHash:          user.PasswordHash,
+			RecentLoginIPs:        user.RecentLoginIPs,
+			TOTPSecret:            user.TOTPSecret,
+			TOTPLastStep:          user.TOTPLastStep,
+			RecoveryCodes:         user.RecoveryCodes,
+			AppPasswordGeneration: user.AppPasswordGeneration,
+		})
+	}
+	sort.Slice(snap.Users, func(i, j int) bool { return snap.Users[i].ID < snap.Users[j].ID })
+
+	if s.systemConfig != nil {
+		config := *s.systemConfig
+		snap.SystemConfig = &config
+	}
+
+	snap.APIKeys = sortedCopies(s.apiKeys)
+	snap.SystemDomains = sortedCopies(s.systemDomains)
+	snap.UserDomains = sortedCopies(s.userDomains)
+
+	for _, m := range s.mailboxes {
+		mb := *m
+		snap.Mailboxes = append(snap.Mailboxes, SnapshotMailbox{
+			Mailbox:               &mb,
+			IPSource:              mb.IPSource,
+			IdleShortened:         mb.IdleShortened,
+			IdleOriginalExpiresAt: mb.IdleOriginalExpiresAt,
+			MessageSeq:            s.messageSeqs[mb.ID],
+		})
+	}
+	sort.Slice(snap.Mailboxes, func(i, j int) bool { return snap.Mailboxes[i].ID < snap.Mailboxes[j].ID })
+
+	snap.Aliases = sortedCopies(s.aliases)
+
+	for _, byID := range s.messages {
+		for _, m := range byID {
+			msg := *m
+			entry := SnapshotMessage{Message: &msg}
+			for i, att := range msg.Attachments {
+				if att == nil || att.Content == nil {
+					continue
+				}
+				if entry.AttachmentContent == nil {
+					entry.AttachmentContent = make([][]byte, len(msg.Attachments))
+				}
+				entry.AttachmentContent[i] = att.Content
+			}
+			snap.Messages = append(snap.Messages, entry)
+		}
+	}
+	sort.Slice(snap.Messages, func(i, j int) bool { return snap.Messages[i].ID < snap.Messages[j].ID })
+
+	snap.Webhooks = sortedCopies(s.webhooks)
+	snap.Tags = sortedCopies(s.tags)
+
+	for _, mt := range s.messageTags {
+		copied := *mt
+		snap.MessageTags = append(snap.MessageTags, &copied)
+	}
+	sort.Slice(snap.MessageTags, func(i, j int) bool {
+		a, b := snap.MessageTags[i], snap.MessageTags[j]
+		return a.MessageID < b.MessageID || (a.MessageID == b.MessageID && a.TagID < b.TagID)
+	})
+
+	snap.Organizations = sortedCopies(s.orgs)
+	for _, members := range s.orgMembers {
+		for _, member := range members {
+			copied := *member
+			snap.OrgMembers = append(snap.OrgMembers, &copied)
+		}
+	}
+	sort.Slice(snap.OrgMembers, func(i, j int) bool {
+		a, b := snap.OrgMembers[i], snap.OrgMembers[j]
+		return a.OrgID < b.OrgID || (a.OrgID == b.OrgID && a.UserID < b.UserID)
+	})
+	for _, invite := range s.orgInvites {
+		copied := *invite
+		snap.OrgInvites = append(snap.OrgInvites, &copied)
+	}
+	sort.Slice(snap.OrgInvites, func(i, j int) bool { return snap.OrgInvites[i].Token < snap.OrgInvites[j].Token })
+
+	snap.DistributionLists = sortedCopies(s.lists)
+	for _, redaction := range s.redactions {
+		copied := *redaction
+		snap.Redactions = append(snap.Redactions, &copied)
+	}
+	sort.Slice(snap.Redactions, func(i, j int) bool { return snap.Redactions[i].MessageID < snap.Redactions[j].MessageID })
+	snap.AbuseReports = sortedCopies(s.abuseReports)
+	for _, share := range sortedCopies(s.messageShares) {
+		snap.MessageShares = append(snap.MessageShares, SnapshotMessageShare{MessageShare: share, Nonce: share.Nonce})
+	}
+	snap.SentMessages = sortedCopies(s.sentMessages)
+	snap.DomainWhitelist = sortedCopies(s.domainWhitelist)
+	snap.ReservedPrefixes = sortedCopies(s.reservedPrefixes)
+	snap.OAuthIdentities = sortedCopies(s.oauthIdentities)
+	snap.UserSessions = sortedCopies(s.userSessions)
+	for _, log := range s.auditLogs {
+		copied := *log
+		snap.AuditLogs = append(snap.AuditLogs, &copied)
+	}
+	snap.OutboxEvents = sortedCopies(s.outboxEvents)
+	for _, forward := range sortedCopies(s.mailboxForwards) {
+		snap.MailboxForwards = append(snap.MailboxForwards, SnapshotMailboxForward{
+			MailboxForward: forward, CodeHash: forward.CodeHash, VerifyAttempts: forward.VerifyAttempts,
+		})
+	}
+	snap.MaintenanceJobs = sortedCopies(s.maintenanceJobs)
+	snap.AnalyticsBuckets = s.analyticsBucketsLocked(func(analyticsKey) bool { return true })
+
+	now := time.Now()
+	for jti, expiresAt := range s.blacklist {
+		if now.Before(expiresAt) {
+			snap.RevokedTokens[jti] = expiresAt
+		}
+	}
+	for id, entry := range s.sessions {
+		if now.Before(entry.ExpiresAt) {
+			snap.Sessions = append(snap.Sessions, SnapshotSession{ID: id, UserID: entry.UserID, ExpiresAt: entry.ExpiresAt})
+		}
+	}
+	sort.Slice(snap.Sessions, func(i, j int) bool { return snap.Sessions[i].ID < snap.Sessions[j].ID })
+
+	return snap
+}
+
+// Restore 用快照替换存储中的全部持久内容并重建索引
+//
+// 运行时状态（投递记录、速率限制等）保持不变。
+func (s *Store) Restore(snap *Snapshot) error {
+	if snap == nil {
+		return errors.New("snapshot is nil")
+	}
+	if snap.Version != SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, snap.Version)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = make(map[string]*domain.User, len(snap.Users))
+	s.byEmail = make(map[string]string, len(snap.Users))
+	s.byUsername = make(map[string]string, len(snap.Users))
+	for _, entry := range snap.Users {
+		if entry.User == nil {
+			continue
+		}
+		user := entry.User
+		user.PasswordHash = entry.PasswordHash
+		user.RecentLoginIPs = entry.RecentLoginIPs
+		user.TOTPSecret = entry.TOTPSecret
+		user.TOTPLastStep = entry.TOTPLastStep
+		user.RecoveryCodes = entry.RecoveryCodes
+		user.AppPasswordGeneration = entry.AppPasswordGeneration
+		s.users[user.ID] = user
+		s.byEmail[user.Email] = user.ID
+		s.byUsername[strings.ToLower(user.Username)] = user.ID
+	}
+
+	s.apiKeys = make(map[string]*domain.APIKey, len(snap.APIKeys))
+	s.byAPIKey = make(map[string]string, len(snap.APIKeys))
+	for _, key := range snap.APIKeys {
+		s.apiKeys[key.ID] = key
+		s.byAPIKey[key.Key] = key.UserID
+	}
+
+	s.systemDomains = make(map[string]*domain.SystemDomain, len(snap.SystemDomains))
+	s.bySystemDomain = make(map[string]string, len(snap.SystemDomains))
+	for _, d := range snap.SystemDomains {
+		s.systemDomains[d.ID] = d
+		s.bySystemDomain[d.Domain] = d.ID
+	}
+
+	s.userDomains = make(map[string]*domain.UserDomain, len(snap.UserDomains))
+	s.byDomain = make(map[string]string, len(snap.UserDomains))
+	for _, d := range snap.UserDomains {
+		s.userDomains[d.ID] = d
+		s.byDomain[d.Domain] = d.ID
+	}
+
+	s.mailboxes = make(map[string]*domain.Mailbox, len(snap.Mailboxes))
+	s.byAddress = make(map[string]string, len(snap.Mailboxes))
+	s.messageSeqs = make(map[string]int64, len(snap.Mailboxes))
+	for _, entry := range snap.Mailboxes {
+		if entry.Mailbox == nil {
+			continue
+		}
+		mb := entry.Mailbox
+		mb.IPSource = entry.IPSource
+		mb.IdleShortened = entry.IdleShortened
+		mb.IdleOriginalExpiresAt = entry.IdleOriginalExpiresAt
+		s.mailboxes[mb.ID] = mb
+		s.messageSeqs[mb.ID] = entry.MessageSeq
+		s.byAddress[mb.Address] = mb.ID
+	}
+
+	s.aliases = make(map[string]*domain.MailboxAlias, len(snap.Aliases))
+	s.byAlias = make(map[string]string, len(snap.Aliases))
+	for _, alias := range snap.Aliases {
+		s.aliases[alias.ID] = alias
+		s.byAlias[alias.Address] = alias.ID
+	}
+
+	s.messages = make(map[string]map[string]*domain.Message)
+	s.searchDocs = make(map[string]*domain.SearchDocument)
+	s.searchPostings = make(map[string]map[string]struct{})
+	for _, entry := range snap.Messages {
+		if entry.Message == nil {
+			continue
+		}
+		msg := entry.Message
+		for i, content := range entry.AttachmentContent {
+			if content != nil && i < len(msg.Attachments) && msg.Attachments[i] != nil {
+				msg.Attachments[i].Content = content
+			}
+		}
+		if s.messages[msg.MailboxID] == nil {
+			s.messages[msg.MailboxID] = make(map[string]*domain.Message)
+		}
+		s.messages[msg.MailboxID][msg.ID] = msg
+		s.indexMessageLocked(domain.NewSearchDocument(msg))
+		if msg.Seq > s.messageSeqs[msg.MailboxID] {
+			s.messageSeqs[msg.MailboxID] = msg.Seq
+		}
+	}
+
+	s.webhooks = make(map[string]*domain.Webhook, len(snap.Webhooks))
+	s.webhooksByUser = make(map[string]map[string]*domain.Webhook)
+	for _, webhook := range snap.Webhooks {
+		s.indexWebhookLocked(webhook)
+	}
+
+	s.tags = make(map[string]*domain.Tag, len(snap.Tags))
+	s.tagsByUser = make(map[string]map[string]*domain.Tag)
+	for _, tag := range snap.Tags {
+		s.tags[tag.ID] = tag
+		if s.tagsByUser[tag.UserID] == nil {
+			s.tagsByUser[tag.UserID] = make(map[string]*domain.Tag)
+		}
+		s.tagsByUser[tag.UserID][tag.ID] = tag
+	}
+
+	s.messageTags = make(map[string]*domain.MessageTag, len(snap.MessageTags))
+	s.tagsByMessage = make(map[string]map[string]*domain.MessageTag)
+	for _, mt := range snap.MessageTags {
+		s.messageTags[mt.MessageID+":"+mt.TagID] = mt
+		if s.tagsByMessage[mt.MessageID] == nil {
+			s.tagsByMessage[mt.MessageID] = make(map[string]*domain.MessageTag)
+		}
+		s.tagsByMessage[mt.MessageID][mt.TagID] = mt
+	}
+
+	s.orgs = make(map[string]*domain.Organization, len(snap.Organizations))
+	for _, org := range snap.Organizations {
+		s.orgs[org.ID] = org
+	}
+	s.orgMembers = make(map[string]map[string]*domain.OrgMember)
+	for _, member := range snap.OrgMembers {
+		if s.orgMembers[member.OrgID] == nil {
+			s.orgMembers[member.OrgID] = make(map[string]*domain.OrgMember)
+		}
+		s.orgMembers[member.OrgID][member.UserID] = member
+	}
+	s.orgInvites = make(map[string]*domain.OrgInvite, len(snap.OrgInvites))
+	for _, invite := range snap.OrgInvites {
+		s.orgInvites[invite.Token] = invite
+	}
+
+	s.lists = make(map[string]*domain.DistributionList, len(snap.DistributionLists))
+	s.listsByAddress = make(map[string]string, len(snap.DistributionLists))
+	for _, list := range snap.DistributionLists {
+		s.lists[list.ID] = list
+		s.listsByAddress[list.Address] = list.ID
+	}
+
+	s.redactions = make(map[string]*domain.MessageRedaction, len(snap.Redactions))
+	for _, redaction := range snap.Redactions {
+		s.redactions[redaction.MessageID] = redaction
+	}
+
+	s.abuseReports = make(map[string]*domain.AbuseReport, len(snap.AbuseReports))
+	for _, report := range snap.AbuseReports {
+		s.abuseReports[report.ID] = report
+	}
+
+	s.messageShares = make(map[string]*domain.MessageShare, len(snap.MessageShares))
+	for _, entry := range snap.MessageShares {
+		if entry.MessageShare == nil {
+			continue
+		}
+		share := entry.MessageShare
+		share.Nonce = entry.Nonce
+		s.messageShares[share.ID] = share
+	}
+
+	s.sentMessages = make(map[string]*domain.SentMessage, len(snap.SentMessages))
+	for _, message := range snap.SentMessages {
+		s.sentMessages[message.ID] = message
+	}
+
+	s.domainWhitelist = make(map[string]*domain.DomainWhitelistEntry, len(snap.DomainWhitelist))
+	for _, entry := range snap.DomainWhitelist {
+		s.domainWhitelist[entry.ID] = entry
+	}
+
+	s.reservedPrefixes = make(map[string]*domain.ReservedPrefix, len(snap.ReservedPrefixes))
+	for _, prefix := range snap.ReservedPrefixes {
+		s.reservedPrefixes[prefix.ID] = prefix
+	}
+
+	s.oauthIdentities = make(map[string]*domain.OAuthIdentity, len(snap.OAuthIdentities))
+	for _, identity := range snap.OAuthIdentities {
+		s.oauthIdentities[identity.ID] = identity
+	}
+
+	s.userSessions = make(map[string]*domain.UserSession, len(snap.UserSessions))
+	for _, session := range snap.UserSessions {
+		s.userSessions[session.ID] = session
+	}
+
+	s.auditLogs = snap.AuditLogs
+
+	s.outboxEvents = make(map[string]*domain.OutboxEvent, len(snap.OutboxEvents))
+	for _, event := range snap.OutboxEvents {
+		s.outboxEvents[event.ID] = event
+	}
+
+	s.mailboxForwards = make(map[string]*domain.MailboxForward, len(snap.MailboxForwards))
+	for _, entry := range snap.MailboxForwards {
+		if entry.MailboxForward == nil {
+			continue
+		}
+		forward := entry.MailboxForward
+		forward.CodeHash = entry.CodeHash
+		forward.VerifyAttempts = entry.VerifyAttempts
+		s.mailboxForwards[forward.ID] = forward
+	}
+	s.forwardDeliveries = make(map[string]*domain.ForwardDelivery)
+
+	s.maintenanceJobs = make(map[string]*domain.MaintenanceJob, len(snap.MaintenanceJobs))
+	for _, job := range snap.MaintenanceJobs {
+		job.SyncActiveType() // 占用标记不序列化，按状态恢复
+		s.maintenanceJobs[job.ID] = job
+	}
+
+	s.analytics = make(map[analyticsKey]int64, len(snap.AnalyticsBuckets))
+	for _, bucket := range snap.AnalyticsBuckets {
+		s.analytics[newAnalyticsKey(bucket)] = bucket.Value
+	}
+
+	if snap.SystemConfig != nil {
+		s.systemConfig = snap.SystemConfig
+	}
+
+	s.blacklist = make(map[string]time.Time, len(snap.RevokedTokens))
+	for jti, expiresAt := range snap.RevokedTokens {
+		s.blacklist[jti] = expiresAt
+	}
+	s.sessions = make(map[string]*sessionEntry, len(snap.Sessions))
+	for _, session := range snap.Sessions {
+		s.sessions[session.ID] = &sessionEntry{UserID: session.UserID, ExpiresAt: session.ExpiresAt}
+	}
+
+	return nil
+}
+
+// WriteSnapshot 以 gzip 压缩的 JSON 写出当前快照
+func (s *Store) WriteSnapshot(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(s.Snapshot()); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadSnapshot 读取 WriteSnapshot 写出的快照
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open snapshot: %w", err)
+	}
+	defer zr.Close()
+
+	var snap Snapshot
+	if err := json.NewDecoder(zr).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if snap.Version != SnapshotVersion {
+		return nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, snap.Version)
+	}
+	return &snap, nil
+}
+
+// SaveSnapshot 将快照写入文件（先写同目录临时文件再重命名，崩溃时不会留下半个快照）
+func (s *Store) SaveSnapshot(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // 重命名成功后为空操作
+
+	if err := s.WriteSnapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// LoadSnapshot 从文件恢复存储；文件不存在时返回 false 且不修改存储
+func (s *Store) LoadSnapshot(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	snap, err := ReadSnapshot(f)
+	if err != nil {
+		return false, err
+	}
+	return true, s.Restore(snap)
+}
+
+// ReadSnapshotFile 读取快照文件（迁移工具使用，不恢复到存储）
+func ReadSnapshotFile(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSnapshot(f)
+}
+
+// sortedCopies 按 ID 排序并浅拷贝实体（快照序列化期间不受后续修改影响）
+func sortedCopies[T any](items map[string]*T) []*T {
+	ids := make([]string, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	result := make([]*T, 0, len(items))
+	for _, id := range ids {
+		copied := *items[id]
+		result = append(result, &copied)
+	}
+	return result
+}
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+)
+
+// seedSnapshotStore 填充每类持久数据（时间戳固定为 UTC，便于逐字段比较）
+func seedSnapshotStore(t *testing.T) *Store {
+	t.Helper()
+	store := NewStore(24 * time.Hour)
+	created := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	expires := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	userID := "user-1"
+
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{
+		ID: userID, Email: "alice@example.com", Username: "Alice", PasswordHash: "bcrypt-hash",
+		Role: domain.RoleUser, Tier: domain.TierPro, IsActive: true, CreatedAt: created, UpdatedAt: created,
+		RecentLoginIPs: []string{"203.0.113.7"},
+	}))
+	require.NoError(t, store.SaveAPIKey(t.Context(), &domain.APIKey{
+		ID: "key-1", UserID: userID, Key: "key-hash", KeyPrefix: "tm_abc", Name: "ci", IsActive: true, CreatedAt: created,
+	}))
+	require.NoError(t, store.SaveSystemDomain(t.Context(), &domain.SystemDomain{
+		ID: "sd-1", Domain: "temp.mail", Status: domain.SystemDomainStatusVerified, IsActive: true, CreatedAt: created,
+	}))
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{
+		ID: "ud-1", UserID: userID, Domain: "alice.dev", Status: domain.DomainStatusVerified, CreatedAt: created, UpdatedAt: created,
+	}))
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "box@temp.mail", LocalPart: "box", Domain: "temp.mail", Token: "tok-1",
+		UserID: &userID, CreatedAt: created, ExpiresAt: &expires, IPSource: "198.51.100.1",
+		IdleShortened: true, IdleOriginalExpiresAt: &expires,
+	}))
+	require.NoError(t, store.SaveAlias(t.Context(), &domain.MailboxAlias{
+		ID: "alias-1", MailboxID: "mb-1", Address: "alias@temp.mail", CreatedAt: created, IsActive: true,
+	}))
+	require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+		ID: "msg-1", MailboxID: "mb-1", From: "sender@example.com", To: "box@temp.mail", Subject: "hello",
+		CreatedAt: created, ReceivedAt: created, Size: 42, Text: "body", Raw: "raw",
+		CapturedHeaders: map[string]string{"X-Test-Run-Id": "7"},
+		Attachments: []*domain.Attachment{
+			{ID: "att-1", MessageID: "msg-1", Filename: "a.txt", ContentType: "text/plain", Size: 3, Content: []byte("abc")},
+			{ID: "att-2", MessageID: "msg-1", Filename: "b.bin", StoragePath: "mb-1/msg-1/att-2"},
+		},
+	}))
+	require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+		ID: "msg-2", MailboxID: "mb-1", Subject: "read", IsRead: true, CreatedAt: created, ReceivedAt: created,
+	}))
+
+	webhook := &domain.Webhook{ID: "wh-1", UserID: userID, URL: "https://hooks.example.com", Events: []string{"message.received"}, Secret: "s3cret", IsActive: true}
+	require.NoError(t, store.CreateWebhook(t.Context(), webhook))
+	webhook.CreatedAt, webhook.UpdatedAt = created, created
+	tag := &domain.Tag{ID: "tag-1", UserID: userID, Name: "ci", Color: "#ff0000"}
+	require.NoError(t, store.CreateTag(t.Context(), tag))
+	tag.CreatedAt, tag.UpdatedAt = created, created
+	require.NoError(t, store.AddMessageTag(t.Context(), "msg-1", "tag-1"))
+	store.messageTags["msg-1:tag-1"].CreatedAt = created
+
+	require.NoError(t, store.CreateOrganization(t.Context(), &domain.Organization{ID: "org-1", Name: "Acme", CreatedAt: created}))
+	require.NoError(t, store.SaveOrgMember(t.Context(), &domain.OrgMember{OrgID: "org-1", UserID: userID, Role: domain.OrgRoleOwner, JoinedAt: created}))
+	require.NoError(t, store.SaveDistributionList(t.Context(), &domain.DistributionList{
+		ID: "list-1", UserID: userID, Address: "team@temp.mail", Members: []string{"a@example.com"}, CreatedAt: created,
+	}))
+	require.NoError(t, store.SaveMessageShare(t.Context(), &domain.MessageShare{
+		ID: "share-1", MailboxID: "mb-1", MessageID: "msg-1", Nonce: "nonce-1", Views: 3, ExpiresAt: expires, CreatedAt: created,
+	}))
+	require.NoError(t, store.AddToBlacklist(t.Context(), "jti-1", time.Hour))
+	require.NoError(t, store.CacheSession(t.Context(), "sess-1", userID, time.Hour))
+	store.sessions["sess-1"].ExpiresAt = expires
+	store.systemConfig.UpdatedAt = created
+	return store
+}
+
+func TestStoreSnapshot(t *testing.T) {
+	t.Run("快照写入文件后恢复，逐字段一致", func(t *testing.T) {
+		original := seedSnapshotStore(t)
+		path := filepath.Join(t.TempDir(), "snapshots", "memory.json.gz")
+		require.NoError(t, original.SaveSnapshot(path))
+
+		restored := NewStore(24 * time.Hour)
+		loaded, err := restored.LoadSnapshot(path)
+		require.NoError(t, err)
+		require.True(t, loaded)
+
+		want, got := original.Snapshot(), restored.Snapshot()
+		assert.Equal(t, want.Users, got.Users)
+		assert.Equal(t, want.APIKeys, got.APIKeys)
+		assert.Equal(t, want.SystemDomains, got.SystemDomains)
+		assert.Equal(t, want.UserDomains, got.UserDomains)
+		assert.Equal(t, want.Mailboxes, got.Mailboxes)
+		assert.Equal(t, want.Aliases, got.Aliases)
+		assert.Equal(t, want.Messages, got.Messages)
+		assert.Equal(t, want.Webhooks, got.Webhooks)
+		assert.Equal(t, want.Tags, got.Tags)
+		assert.Equal(t, want.MessageTags, got.MessageTags)
+		assert.Equal(t, want.Organizations, got.Organizations)
+		assert.Equal(t, want.OrgMembers, got.OrgMembers)
+		assert.Equal(t, want.DistributionLists, got.DistributionLists)
+		assert.Equal(t, want.MessageShares, got.MessageShares)
+		assert.Equal(t, want.SystemConfig, got.SystemConfig)
+		assert.Equal(t, want.Sessions, got.Sessions)
+		assert.Len(t, got.RevokedTokens, 1)
+	})
+
+	t.Run("恢复后索引可用，不对外序列化的字段保留", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "memory.json.gz")
+		require.NoError(t, seedSnapshotStore(t).SaveSnapshot(path))
+		restored := NewStore(24 * time.Hour)
+		_, err := restored.LoadSnapshot(path)
+		require.NoError(t, err)
+
+		user, err := restored.GetUserByUsername(t.Context(), "alice")
+		require.NoError(t, err)
+		assert.Equal(t, "bcrypt-hash", user.PasswordHash)
+		assert.Equal(t, []string{"203.0.113.7"}, user.RecentLoginIPs)
+
+		keyUser, err := restored.GetUserByAPIKey(t.Context(), "key-hash")
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", keyUser.ID)
+
+		mailbox, err := restored.GetMailboxByAddress(t.Context(), "box@temp.mail")
+		require.NoError(t, err)
+		assert.Equal(t, "198.51.100.1", mailbox.IPSource)
+		assert.True(t, mailbox.IdleShortened)
+		assert.Equal(t, 2, mailbox.TotalCount)
+		assert.Equal(t, 1, mailbox.Unread)
+
+		alias, err := restored.GetAliasByAddress(t.Context(), "alias@temp.mail")
+		require.NoError(t, err)
+		assert.Equal(t, "mb-1", alias.MailboxID)
+
+		msg, err := restored.GetMessage(t.Context(), "mb-1", "msg-1")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("abc"), msg.Attachments[0].Content)
+		assert.Nil(t, msg.Attachments[1].Content)
+
+		tags, err := restored.GetMessageTags(t.Context(), "msg-1")
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+		assert.Equal(t, "ci", tags[0].Name)
+
+		list, err := restored.GetDistributionListByAddress(t.Context(), "team@temp.mail")
+		require.NoError(t, err)
+		assert.Equal(t, "list-1", list.ID)
+
+		share, err := restored.GetMessageShare(t.Context(), "share-1")
+		require.NoError(t, err)
+		assert.Equal(t, "nonce-1", share.Nonce, "撤销校验依赖 nonce")
+		assert.Equal(t, int64(3), share.Views)
+
+		revoked, err := restored.IsBlacklisted(t.Context(), "jti-1")
+		require.NoError(t, err)
+		assert.True(t, revoked)
+		sessionUser, err := restored.GetCachedSession(t.Context(), "sess-1")
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", sessionUser)
+	})
+
+	t.Run("原子写入：只留下快照文件，权限仅所有者可读", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "memory.json.gz")
+		store := seedSnapshotStore(t)
+		require.NoError(t, store.SaveSnapshot(path))
+		require.NoError(t, store.SaveSnapshot(path)) // 覆盖已有快照
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "memory.json.gz", entries[0].Name())
+		info, err := entries[0].Info()
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("快照文件不存在时不修改存储", func(t *testing.T) {
+		store := seedSnapshotStore(t)
+		loaded, err := store.LoadSnapshot(filepath.Join(t.TempDir(), "missing.json.gz"))
+		require.NoError(t, err)
+		assert.False(t, loaded)
+		_, err = store.GetUserByID(t.Context(), "user-1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("损坏或版本不符的快照返回错误", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "broken.json.gz")
+		require.NoError(t, os.WriteFile(path, []byte("not gzip"), 0o600))
+		_, err := NewStore(time.Hour).LoadSnapshot(path)
+		assert.Error(t, err)
+
+		assert.ErrorIs(t, NewStore(time.Hour).Restore(&Snapshot{Version: SnapshotVersion + 1}), ErrSnapshotVersion)
+	})
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/domain"
+)
+
+// GetMessageStats 统计邮件收件情况（内存存储实现，扫描邮件表）
+func (s *Store) GetMessageStats(ctx context.Context, query domain.MessageStatsQuery) (*domain.MessageStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := &domain.MessageStats{
+		Since:     query.Since,
+		Until:     query.Until,
+		ByMailbox: make(map[string]int),
+	}
+
+	hourly := make(map[time.Time]int)
+	senders := make(map[string]int)
+	var totalSize int64
+	for _, mailboxID := range query.MailboxIDs {
+		for _, msg := range s.messages[mailboxID] {
+			received := msg.ReceivedAt.UTC()
+			if received.Before(query.Since) || !received.Before(query.Until) {
+				continue
+			}
+			stats.Total++
+			if !msg.IsRead {
+				stats.Unread++
+			}
+			totalSize += msg.Size
+			hourly[received.Truncate(time.Hour)]++
+			senders[strings.ToLower(msg.From)]++
+			stats.ByMailbox[mailboxID]++
+		}
+	}
+
+	if stats.Total > 0 {
+		stats.AverageSize = totalSize / int64(stats.Total)
+	}
+
+	stats.Hourly = make([]domain.HourlyCount, 0, len(hourly))
+	for hour, count := range hourly {
+		stats.Hourly = append(stats.Hourly, domain.HourlyCount{Hour: hour, Count: count})
+	}
+	sort.Slice(stats.Hourly, func(i, j int) bool {
+		return stats.Hourly[i].Hour.Before(stats.Hourly[j].Hour)
+	})
+
+	stats.TopSenders = make([]domain.SenderCount, 0, len(senders))
+	for sender, count := range senders {
+		stats.TopSenders = append(stats.TopSenders, domain.SenderCount{Sender: sender, Count: count})
+	}
+	sort.Slice(stats.TopSenders, func(i, j int) bool {
+		if stats.TopSenders[i].Count != stats.TopSenders[j].Count {
+			return stats.TopSenders[i].Count > stats.TopSenders[j].Count
+		}
+		return stats.TopSenders[i].Sender < stats.TopSenders[j].Sender
+	})
+	if query.TopSenders > 0 && len(stats.TopSenders) > query.TopSenders {
+		stats.TopSenders = stats.TopSenders[:query.TopSenders]
+	}
+
+	return stats, nil
+}
@@ -1,16 +1,19 @@
 package memory
 
 import (
+	"context"
 	"errors"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
 )
 
 var (
-	ErrMailboxNotFound = errors.New("mailbox not found")
+	ErrMailboxNotFound = storage.ErrMailboxNotFound
 	ErrMessageNotFound = errors.New("message not found")
 	ErrUserNotFound    = errors.New("user not found")
 	ErrEmailExists     = errors.New("email already exists")
@@ -22,6 +25,7 @@ type Store struct {
 	mailboxes      map[string]*domain.Mailbox
 	byAddress      map[string]string
 	messages       map[string]map[string]*domain.Message // mailboxID -> messageID -> message
+	messageSeqs    map[string]int64                      // mailboxID -> 最近分配的邮件序号
 	users          map[string]*domain.User               // userID -> user
 	byEmail        map[string]string                     // email -> userID
 	byUsername     map[string]string                     // username -> userID
@@ -46,6 +50,66 @@ type Store struct {
 	messageTags   map[string]*domain.MessageTag            // 按 "messageID:tagID" 索引
 	tagsByMessage map[string]map[string]*domain.MessageTag // 按邮件 ID 索引
 
+	// 组织存储
+	orgs       map[string]*domain.Organization         // 按 ID 索引
+	orgMembers map[string]map[string]*domain.OrgMember // orgID -> userID -> member
+	orgInvites map[string]*domain.OrgInvite            // 按加入令牌索引
+
+	// 分发列表存储
+	lists          map[string]*domain.DistributionList           // 按 ID 索引
+	listsByAddress map[string]string                             // address -> listID
+	listDeliveries map[string][]*domain.DistributionListDelivery // 投递报告（按列表 ID）
+
+	// 邮件脱敏副本（按邮件 ID 索引）
+	redactions map[string]*domain.MessageRedaction
+
+	// 滥用举报（按 ID 索引）
+	abuseReports map[string]*domain.AbuseReport
+
+	// 邮件分享链接（按 ID 索引）
+	messageShares map[string]*domain.MessageShare
+
+	// 已发送邮件（按 ID 索引）
+	sentMessages map[string]*domain.SentMessage
+
+	// 用户域名白名单（按 ID 索引）
+	domainWhitelist map[string]*domain.DomainWhitelistEntry
+
+	// 系统域名保留前缀（按 ID 索引）
+	reservedPrefixes map[string]*domain.ReservedPrefix
+
+	// 第三方登录身份（按 ID 索引）
+	oauthIdentities map[string]*domain.OAuthIdentity
+
+	// 登录会话（按 ID 索引）
+	userSessions map[string]*domain.UserSession
+
+	// 管理操作审计记录（按写入顺序）
+	auditLogs []*domain.AuditLog
+
+	// 发件箱事件（按 ID 索引）
+	outboxEvents map[string]*domain.OutboxEvent
+
+	// 邮箱转发地址及其投递队列（按 ID 索引）
+	mailboxForwards   map[string]*domain.MailboxForward
+	forwardDeliveries map[string]*domain.ForwardDelivery
+
+	// 全文索引：索引文档（按邮件 ID）和倒排表（词项 → 邮件 ID 集合），不写入快照，恢复时由邮件正文重建
+	searchDocs     map[string]*domain.SearchDocument
+	searchPostings map[string]map[string]struct{}
+
+	// 维护任务（按 ID 索引）
+	maintenanceJobs map[string]*domain.MaintenanceJob
+
+	// 使用情况统计（按指标、维度、时间桶索引）
+	analytics map[analyticsKey]int64
+
+	// 收信流量计数（按类别、维度、分钟索引，不写入快照）
+	mailFlow map[mailFlowKey]int64
+
+	// 黑洞模式统计（按域名索引）
+	sinks map[string]*sinkCounter
+
 	// 系统配置
 	systemConfig *domain.SystemConfig
 
@@ -53,6 +117,10 @@ type Store struct {
 	rateLimits        map[string]*rateLimitEntry
 	rateLimitsCleanup time.Time // 下次清理过期速率限制的时间
 
+	// JWT 黑名单和会话（值为过期时间 / 会话条目，读取时惰性清理）
+	blacklist map[string]time.Time
+	sessions  map[string]*sessionEntry
+
 	ttl time.Duration
 }
 
@@ -62,12 +130,19 @@ type rateLimitEntry struct {
 	ExpiresAt time.Time
 }
 
+// sessionEntry 会话条目
+type sessionEntry struct {
+	UserID    string
+	ExpiresAt time.Time
+}
+
 // NewStore 创建一个内存存储实例。
 func NewStore(ttl time.Duration) *Store {
 	return &Store{
 		mailboxes:         make(map[string]*domain.Mailbox),
 		byAddress:         make(map[string]string),
 		messages:          make(map[string]map[string]*domain.Message),
+		messageSeqs:       make(map[string]int64),
 		users:             make(map[string]*domain.User),
 		byEmail:           make(map[string]string),
 		byUsername:        make(map[string]string),
@@ -87,27 +162,89 @@ func NewStore(ttl time.Duration) *Store {
 		tagsByUser:        make(map[string]map[string]*domain.Tag),
 		messageTags:       make(map[string]*domain.MessageTag),
 		tagsByMessage:     make(map[string]map[string]*domain.MessageTag),
+		orgs:              make(map[string]*domain.Organization),
+		orgMembers:        make(map[string]map[string]*domain.OrgMember),
+		orgInvites:        make(map[string]*domain.OrgInvite),
+		lists:             make(map[string]*domain.DistributionList),
+		listsByAddress:    make(map[string]string),
+		listDeliveries:    make(map[string][]*domain.DistributionListDelivery),
+		redactions:        make(map[string]*domain.MessageRedaction),
+		abuseReports:      make(map[string]*domain.AbuseReport),
+		messageShares:     make(map[string]*domain.MessageShare),
+		sentMessages:      make(map[string]*domain.SentMessage),
+		domainWhitelist:   make(map[string]*domain.DomainWhitelistEntry),
+		reservedPrefixes:  make(map[string]*domain.ReservedPrefix),
+		oauthIdentities:   make(map[string]*domain.OAuthIdentity),
+		userSessions:      make(map[string]*domain.UserSession),
+		outboxEvents:      make(map[string]*domain.OutboxEvent),
+		mailboxForwards:   make(map[string]*domain.MailboxForward),
+		forwardDeliveries: make(map[string]*domain.ForwardDelivery),
+		searchDocs:        make(map[string]*domain.SearchDocument),
+		searchPostings:    make(map[string]map[string]struct{}),
+		maintenanceJobs:   make(map[string]*domain.MaintenanceJob),
+		analytics:         make(map[analyticsKey]int64),
+		mailFlow:          make(map[mailFlowKey]int64),
+		sinks:             make(map[string]*sinkCounter),
 		systemConfig:      domain.DefaultSystemConfig(),
 		rateLimits:        make(map[string]*rateLimitEntry),
 		rateLimitsCleanup: time.Now().Add(5 * time.Minute),
+		blacklist:         make(map[string]time.Time),
+		sessions:          make(map[string]*sessionEntry),
 		ttl:               ttl,
 	}
 }
 
-// SaveMailbox 保存邮箱信息。
-func (s *Store) SaveMailbox(mailbox *domain.Mailbox) error {
+// SetDefaultTTL 修改未设置过期时间的邮箱的默认有效期（系统配置修改后调用）
+func (s *Store) SetDefaultTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl = ttl
+}
+
+// CreateMailbox 创建新邮箱，地址已被邮箱或别名占用时返回 storage.ErrAddressTaken。
+func (s *Store) CreateMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.pruneExpiredLocked()
 
+	if _, ok := s.mailboxes[mailbox.ID]; ok {
+		return storage.ErrAddressTaken
+	}
+	if s.addressTakenLocked(mailbox.Address, mailbox.ID) {
+		return storage.ErrAddressTaken
+	}
 	s.mailboxes[mailbox.ID] = mailbox
 	s.byAddress[mailbox.Address] = mailbox.ID
 	return nil
 }
 
+// SaveMailbox 保存邮箱信息，地址已属于其他邮箱或别名时返回 storage.ErrAddressTaken。
+func (s *Store) SaveMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneExpiredLocked()
+
+	if s.addressTakenLocked(mailbox.Address, mailbox.ID) {
+		return storage.ErrAddressTaken
+	}
+	s.mailboxes[mailbox.ID] = mailbox
+	s.byAddress[mailbox.Address] = mailbox.ID
+	return nil
+}
+
+// addressTakenLocked 地址是否已被 mailboxID 以外的邮箱或任一别名占用
+func (s *Store) addressTakenLocked(address, mailboxID string) bool {
+	if existingID, ok := s.byAddress[address]; ok && existingID != mailboxID {
+		return true
+	}
+	_, ok := s.byAlias[address]
+	return ok
+}
+
 // GetMailbox 根据 ID 获取邮箱。
-func (s *Store) GetMailbox(id string) (*domain.Mailbox, error) {
+func (s *Store) GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error) {
 	s.mu.RLock()
 	mailbox, ok := s.mailboxes[id]
 	s.mu.RUnlock()
@@ -124,18 +261,38 @@ func (s *Store) GetMailbox(id string) (*domain.Mailbox, error) {
 }
 
 // GetMailboxByAddress 根据完整地址获取邮箱。
-func (s *Store) GetMailboxByAddress(address string) (*domain.Mailbox, error) {
+func (s *Store) GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error) {
 	s.mu.RLock()
 	id, ok := s.byAddress[address]
 	s.mu.RUnlock()
 	if !ok {
 		return nil, ErrMailboxNotFound
 	}
-	return s.GetMailbox(id)
+	return s.GetMailbox(ctx, id)
+}
+
+// GetMailboxesByAddresses 批量按地址查询未过期的邮箱，不存在的地址直接忽略。
+func (s *Store) GetMailboxesByAddresses(ctx context.Context, addresses []string) ([]domain.Mailbox, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.Mailbox, 0, len(addresses))
+	seen := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		id, ok := s.byAddress[address]
+		if !ok || seen[id] {
+			continue
+		}
+		if mb, ok := s.mailboxes[id]; ok && !mailboxExpired(mb, s.ttl) {
+			seen[id] = true
+			result = append(result, *mb)
+		}
+	}
+	return result, nil
 }
 
 // ListMailboxes 返回全部邮箱的快照。
-func (s *Store) ListMailboxes() []domain.Mailbox {
+func (s *Store) ListMailboxes(ctx context.Context) []domain.Mailbox {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -152,7 +309,7 @@ func (s *Store) ListMailboxes() []domain.Mailbox {
 }
 
 // DeleteMailbox 删除指定邮箱。
-func (s *Store) DeleteMailbox(id string) error {
+func (s *Store) DeleteMailbox(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -164,7 +321,7 @@ func (s *Store) DeleteMailbox(id string) error {
 }
 
 // ListMailboxesByUserID 返回指定用户的全部邮箱。
-func (s *Store) ListMailboxesByUserID(userID string) []domain.Mailbox {
+func (s *Store) ListMailboxesByUserID(ctx context.Context, userID string) []domain.Mailbox {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -183,8 +340,82 @@ func (s *Store) ListMailboxesByUserID(userID string) []domain.Mailbox {
 	return result
 }
 
+// ListMailboxesByOrgID 返回指定组织的全部邮箱。
+func (s *Store) ListMailboxesByOrgID(ctx context.Context, orgID string) []domain.Mailbox {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneExpiredLocked()
+
+	result := make([]domain.Mailbox, 0)
+	for _, mb := range s.mailboxes {
+		if mailboxExpired(mb, s.ttl) {
+			continue
+		}
+		if mb.OrgID != nil && *mb.OrgID == orgID {
+			result = append(result, *mb)
+		}
+	}
+	return result
+}
+
+// ListMailboxSummariesByUserID 返回指定用户的邮箱摘要（持锁一次遍历完成统计）。
+func (s *Store) ListMailboxSummariesByUserID(ctx context.Context, userID string) ([]domain.MailboxSummary, error) {
+	return s.listMailboxSummaries(func(mb *domain.Mailbox) bool {
+		return mb.UserID != nil && *mb.UserID == userID
+	}), nil
+}
+
+// ListMailboxSummariesByOrgID 返回指定组织的邮箱摘要。
+func (s *Store) ListMailboxSummariesByOrgID(ctx context.Context, orgID string) ([]domain.MailboxSummary, error) {
+	return s.listMailboxSummaries(func(mb *domain.Mailbox) bool {
+		return mb.OrgID != nil && *mb.OrgID == orgID
+	}), nil
+}
+
+// listMailboxSummaries 统计匹配邮箱的收件箱邮件数、未读数、占用字节和最近一封邮件
+func (s *Store) listMailboxSummaries(match func(mb *domain.Mailbox) bool) []domain.MailboxSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneExpiredLocked()
+
+	result := make([]domain.MailboxSummary, 0)
+	for _, mb := range s.mailboxes {
+		if mailboxExpired(mb, s.ttl) || !match(mb) {
+			continue
+		}
+		summary := domain.MailboxSummary{Mailbox: *mb}
+		summary.TotalCount, summary.Unread = 0, 0
+		var latest *domain.Message
+		for _, msg := range s.messages[mb.ID] {
+			summary.StorageBytes += msg.Size
+			if msg.Quarantined {
+				continue
+			}
+			summary.TotalCount++
+			if !msg.IsRead {
+				summary.Unread++
+			}
+			if latest == nil || msg.Seq > latest.Seq || (msg.Seq == latest.Seq && msg.ReceivedAt.After(latest.ReceivedAt)) {
+				latest = msg
+			}
+		}
+		if latest != nil {
+			summary.LastMessage = &domain.MessagePreview{
+				ID:         latest.ID,
+				Subject:    latest.Subject,
+				From:       latest.From,
+				ReceivedAt: latest.ReceivedAt,
+			}
+		}
+		result = append(result, summary)
+	}
+	return result
+}
+
 // DeleteExpiredMailboxes 删除所有过期的邮箱，返回删除数量。
-func (s *Store) DeleteExpiredMailboxes() (int, error) {
+func (s *Store) DeleteExpiredMailboxes(ctx context.Context) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -199,16 +430,158 @@ func (s *Store) DeleteExpiredMailboxes() (int, error) {
 	return count, nil
 }
 
+// ListExpiredMailboxes 列出在指定时间已过期、尚未清理的邮箱。
+func (s *Store) ListExpiredMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.Mailbox, 0)
+	for _, mb := range s.mailboxes {
+		if mailboxExpiredAt(mb, now, s.ttl) {
+			result = append(result, *mb)
+		}
+	}
+	return result, nil
+}
+
+// TouchMailbox 记录访问时间，并恢复闲置时被缩短的有效期
+func (s *Store) TouchMailbox(ctx context.Context, mailboxID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mb, ok := s.mailboxes[mailboxID]
+	if !ok {
+		return ErrMailboxNotFound
+	}
+	accessed := at
+	mb.LastAccessedAt = &accessed
+	if mb.IdleShortened {
+		mb.ExpiresAt = mb.IdleOriginalExpiresAt
+	}
+	mb.IdleSince = nil
+	mb.IdleShortened = false
+	mb.IdleOriginalExpiresAt = nil
+	return nil
+}
+
+// ListIdleMailboxes 列出 before 之后未被访问、尚未标记闲置且未过期的用户邮箱
+func (s *Store) ListIdleMailboxes(ctx context.Context, before, now time.Time) ([]domain.Mailbox, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.Mailbox, 0)
+	for _, mb := range s.mailboxes {
+		if mb.UserID == nil || mb.IdleSince != nil || mailboxExpiredAt(mb, now, s.ttl) {
+			continue
+		}
+		lastActive := mb.CreatedAt
+		if mb.LastAccessedAt != nil {
+			lastActive = *mb.LastAccessedAt
+		}
+		if lastActive.Before(before) {
+			result = append(result, *mb)
+		}
+	}
+	return result, nil
+}
+
+// ListPublicMailboxes 列出未过期的公开收件箱（按地址排序）
+func (s *Store) ListPublicMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.Mailbox, 0)
+	for _, mb := range s.mailboxes {
+		if mb.IsPublic && !mailboxExpiredAt(mb, now, s.ttl) {
+			result = append(result, *mb)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Address < result[j].Address })
+	return result, nil
+}
+
+// MarkMailboxIdle 标记邮箱闲置，shortenTo 非空时缩短有效期
+func (s *Store) MarkMailboxIdle(ctx context.Context, mailboxID string, at time.Time, shortenTo *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mb, ok := s.mailboxes[mailboxID]
+	if !ok {
+		return ErrMailboxNotFound
+	}
+	idleSince := at
+	mb.IdleSince = &idleSince
+	if shortenTo != nil {
+		if !mb.IdleShortened {
+			mb.IdleOriginalExpiresAt = mb.ExpiresAt
+		}
+		expiresAt := *shortenTo
+		mb.ExpiresAt = &expiresAt
+		mb.IdleShortened = true
+	}
+	return nil
+}
+
+// ExtendMailbox 设置邮箱新的过期时间并清除闲置状态
+func (s *Store) ExtendMailbox(ctx context.Context, mailboxID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mb, ok := s.mailboxes[mailboxID]
+	if !ok || mailboxExpired(mb, s.ttl) {
+		return ErrMailboxNotFound
+	}
+	mb.ExpiresAt = &expiresAt
+	mb.IdleSince = nil
+	mb.IdleShortened = false
+	mb.IdleOriginalExpiresAt = nil
+	return nil
+}
+
+// deleteMailboxLocked 删除邮箱及其邮件、邮件标签和别名（调用方持有写锁）
 func (s *Store) deleteMailboxLocked(id string) {
 	if mb, ok := s.mailboxes[id]; ok {
 		delete(s.byAddress, mb.Address)
 	}
+	for messageID := range s.messages[id] {
+		s.deleteMessageTagsLocked(messageID)
+		delete(s.redactions, messageID)
+		s.unindexMessageLocked(messageID)
+	}
+	for shareID, share := range s.messageShares {
+		if share.MailboxID == id {
+			delete(s.messageShares, shareID)
+		}
+	}
+	for sentID, sent := range s.sentMessages {
+		if sent.MailboxID == id {
+			delete(s.sentMessages, sentID)
+		}
+	}
+	for forwardID, forward := range s.mailboxForwards {
+		if forward.MailboxID == id {
+			delete(s.mailboxForwards, forwardID)
+		}
+	}
+	for deliveryID, delivery := range s.forwardDeliveries {
+		if delivery.MailboxID == id {
+			delete(s.forwardDeliveries, deliveryID)
+		}
+	}
+	for aliasID, alias := range s.aliases {
+		if alias.MailboxID == id {
+			delete(s.aliases, aliasID)
+			delete(s.byAlias, alias.Address)
+		}
+	}
+	s.deleteMailboxWebhooksLocked(id)
 	delete(s.mailboxes, id)
 	delete(s.messages, id)
+	delete(s.messageSeqs, id)
 }
 
 // SaveMessage 保存邮件信息。
-func (s *Store) SaveMessage(message *domain.Message) error {
+func (s *Store) SaveMessage(ctx context.Context, message *domain.Message) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -221,6 +594,7 @@ func (s *Store) SaveMessage(message *domain.Message) error {
 	if _, ok := s.messages[message.MailboxID]; !ok {
 		s.messages[message.MailboxID] = make(map[string]*domain.Message)
 	}
+	s.assignSeqLocked(message)
 	s.messages[message.MailboxID][message.ID] = message
 
 	mb := s.mailboxes[message.MailboxID]
@@ -232,8 +606,53 @@ func (s *Store) SaveMessage(message *domain.Message) error {
 	return nil
 }
 
-// ListMessages 返回某个邮箱下的全部邮件。
-func (s *Store) ListMessages(mailboxID string) ([]domain.Message, error) {
+// assignSeqLocked 分配邮箱内的下一个序号；已带序号（快照迁移、重复保存）时保留并推进计数（调用方持有写锁）
+func (s *Store) assignSeqLocked(message *domain.Message) {
+	if message.Seq == 0 {
+		s.messageSeqs[message.MailboxID]++
+		message.Seq = s.messageSeqs[message.MailboxID]
+		return
+	}
+	if message.Seq > s.messageSeqs[message.MailboxID] {
+		s.messageSeqs[message.MailboxID] = message.Seq
+	}
+}
+
+// SaveMessages 批量保存邮件，任一邮箱不存在时全部不保存。
+func (s *Store) SaveMessages(ctx context.Context, messages []*domain.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveMessagesLocked(messages)
+}
+
+// saveMessagesLocked 批量保存邮件（调用方持有写锁）
+func (s *Store) saveMessagesLocked(messages []*domain.Message) error {
+	s.pruneExpiredLocked()
+
+	for _, message := range messages {
+		if _, ok := s.mailboxes[message.MailboxID]; !ok {
+			return ErrMailboxNotFound
+		}
+	}
+	for _, message := range messages {
+		if _, ok := s.messages[message.MailboxID]; !ok {
+			s.messages[message.MailboxID] = make(map[string]*domain.Message)
+		}
+		s.assignSeqLocked(message)
+		s.messages[message.MailboxID][message.ID] = message
+
+		mb := s.mailboxes[message.MailboxID]
+		mb.TotalCount++
+		if !message.IsRead {
+			mb.Unread++
+		}
+	}
+	return nil
+}
+
+// ListMessages 返回某个邮箱下的全部邮件（按序号倒序）。
+func (s *Store) ListMessages(ctx context.Context, mailboxID string) ([]domain.Message, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -252,12 +671,32 @@ func (s *Store) ListMessages(mailboxID string) ([]domain.Message, error) {
 	for _, msg := range msgMap {
 		result = append(result, *msg)
 	}
+	domain.SortMessagesBySeq(result)
 
 	return result, nil
 }
 
+// ListMessagesPage 按条件过滤、排序并分页列出邮件。
+func (s *Store) ListMessagesPage(ctx context.Context, query domain.MessageListQuery) (*domain.MessagePage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneExpiredLocked()
+
+	if _, ok := s.mailboxes[query.MailboxID]; !ok {
+		return nil, ErrMailboxNotFound
+	}
+
+	msgMap := s.messages[query.MailboxID]
+	all := make([]domain.Message, 0, len(msgMap))
+	for _, msg := range msgMap {
+		all = append(all, *msg)
+	}
+	return domain.PageMessages(all, query), nil
+}
+
 // GetMessage 获取单封邮件。
-func (s *Store) GetMessage(mailboxID, messageID string) (*domain.Message, error) {
+func (s *Store) GetMessage(ctx context.Context, mailboxID, messageID string) (*domain.Message, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -277,7 +716,7 @@ func (s *Store) GetMessage(mailboxID, messageID string) (*domain.Message, error)
 }
 
 // MarkMessageRead 将邮件标记为已读。
-func (s *Store) MarkMessageRead(mailboxID, messageID string) error {
+func (s *Store) MarkMessageRead(ctx context.Context, mailboxID, messageID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -301,8 +740,28 @@ func (s *Store) MarkMessageRead(mailboxID, messageID string) error {
 	return nil
 }
 
+// MarkMessageUnread 将邮件标记为未读。
+func (s *Store) MarkMessageUnread(ctx context.Context, mailboxID, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.messages[mailboxID][messageID]
+	if !ok {
+		return ErrMessageNotFound
+	}
+
+	if msg.IsRead {
+		msg.IsRead = false
+		if mb, ok := s.mailboxes[mailboxID]; ok {
+			mb.Unread++
+		}
+	}
+
+	return nil
+}
+
 // DeleteMessage 删除指定邮件。
-func (s *Store) DeleteMessage(mailboxID, messageID string) error {
+func (s *Store) DeleteMessage(ctx context.Context, mailboxID, messageID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -324,14 +783,16 @@ func (s *Store) DeleteMessage(mailboxID, messageID string) error {
 		}
 	}
 
-	// 删除消息
+	// 删除消息及其脱敏副本
 	delete(msgMap, messageID)
+	delete(s.redactions, messageID)
+	s.unindexMessageLocked(messageID)
 
 	return nil
 }
 
 // DeleteAllMessages 删除邮箱中的所有消息，返回删除数量。
-func (s *Store) DeleteAllMessages(mailboxID string) (int, error) {
+func (s *Store) DeleteAllMessages(ctx context.Context, mailboxID string) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -353,12 +814,76 @@ func (s *Store) DeleteAllMessages(mailboxID string) (int, error) {
 		mb.Unread = 0
 	}
 
-	// 删除所有消息
+	// 删除所有消息及其脱敏副本
+	for messageID := range msgMap {
+		delete(s.redactions, messageID)
+		s.unindexMessageLocked(messageID)
+	}
 	delete(s.messages, mailboxID)
 
 	return count, nil
 }
 
+// SetMessageExpiry 设置单封邮件的删除时间。
+func (s *Store) SetMessageExpiry(ctx context.Context, mailboxID, messageID string, expiresAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.messages[mailboxID][messageID]
+	if !ok {
+		return ErrMessageNotFound
+	}
+	msg.ExpiresAt = expiresAt
+	return nil
+}
+
+// SetMessageQuarantined 将邮件移入或移出隔离区。
+func (s *Store) SetMessageQuarantined(ctx context.Context, mailboxID, messageID string, quarantined bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.messages[mailboxID][messageID]
+	if !ok {
+		return ErrMessageNotFound
+	}
+	msg.Quarantined = quarantined
+	return nil
+}
+
+// DeleteExpiredMessages 删除按规则到期的邮件并修正邮箱统计，按邮箱 ID 排序返回。
+func (s *Store) DeleteExpiredMessages(ctx context.Context, now time.Time) ([]domain.ExpiredMessages, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []domain.ExpiredMessages
+	for mailboxID, msgMap := range s.messages {
+		batch := domain.ExpiredMessages{MailboxID: mailboxID}
+		for messageID, msg := range msgMap {
+			if msg.ExpiresAt == nil || msg.ExpiresAt.After(now) {
+				continue
+			}
+			batch.MessageIDs = append(batch.MessageIDs, messageID)
+			if !msg.IsRead {
+				batch.Unread++
+			}
+			delete(msgMap, messageID)
+			delete(s.redactions, messageID)
+			s.unindexMessageLocked(messageID)
+		}
+		if len(batch.MessageIDs) == 0 {
+			continue
+		}
+		if mb, ok := s.mailboxes[mailboxID]; ok {
+			mb.TotalCount -= len(batch.MessageIDs)
+			mb.Unread = max(mb.Unread-batch.Unread, 0)
+		}
+		sort.Strings(batch.MessageIDs)
+		expired = append(expired, batch)
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].MailboxID < expired[j].MailboxID })
+	return expired, nil
+}
+
 // pruneExpiredLocked 清理过期邮箱。
 func (s *Store) pruneExpiredLocked() {
 	now := time.Now()
@@ -388,7 +913,7 @@ func mailboxExpiredAt(mailbox *domain.Mailbox, now time.Time, ttl time.Duration)
 // ========== User Repository ==========
 
 // CreateUser 创建新用户
-func (s *Store) CreateUser(user *domain.User) error {
+func (s *Store) CreateUser(ctx context.Context, user *domain.User) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -424,7 +949,7 @@ func (s *Store) CreateUser(user *domain.User) error {
 }
 
 // GetUserByID 根据ID获取用户
-func (s *Store) GetUserByID(id string) (*domain.User, error) {
+func (s *Store) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -437,7 +962,7 @@ func (s *Store) GetUserByID(id string) (*domain.User, error) {
 }
 
 // GetUserByEmail 根据邮箱获取用户
-func (s *Store) GetUserByEmail(email string) (*domain.User, error) {
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -455,7 +980,7 @@ func (s *Store) GetUserByEmail(email string) (*domain.User, error) {
 }
 
 // GetUserByUsername 根据用户名获取用户
-func (s *Store) GetUserByUsername(username string) (*domain.User, error) {
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -473,7 +998,7 @@ func (s *Store) GetUserByUsername(username string) (*domain.User, error) {
 }
 
 // UpdateUser 更新用户信息
-func (s *Store) UpdateUser(user *domain.User) error {
+func (s *Store) UpdateUser(ctx context.Context, user *domain.User) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -491,11 +1016,11 @@ func (s *Store) UpdateUser(user *domain.User) error {
 	}
 
 	newUsername := strings.ToLower(user.Username)
+	if id, exists := s.byUsername[newUsername]; exists && id != user.ID {
+		return ErrEmailExists
+	}
 	if oldUsername != "" && oldUsername != newUsername {
 		delete(s.byUsername, oldUsername)
-		if _, exists := s.byUsername[newUsername]; exists {
-			return ErrEmailExists
-		}
 	}
 
 	s.users[user.ID] = user
@@ -505,7 +1030,7 @@ func (s *Store) UpdateUser(user *domain.User) error {
 }
 
 // UpdateLastLogin 更新用户最后登录时间
-func (s *Store) UpdateLastLogin(userID string) error {
+func (s *Store) UpdateLastLogin(ctx context.Context, userID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -522,7 +1047,7 @@ func (s *Store) UpdateLastLogin(userID string) error {
 }
 
 // GetUserByAPIKey 根据API Key获取用户
-func (s *Store) GetUserByAPIKey(apiKey string) (*domain.User, error) {
+func (s *Store) GetUserByAPIKey(ctx context.Context, apiKey string) (*domain.User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -540,7 +1065,7 @@ func (s *Store) GetUserByAPIKey(apiKey string) (*domain.User, error) {
 }
 
 // SaveAPIKey 保存API Key
-func (s *Store) SaveAPIKey(apiKey *domain.APIKey) error {
+func (s *Store) SaveAPIKey(ctx context.Context, apiKey *domain.APIKey) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -551,7 +1076,7 @@ func (s *Store) SaveAPIKey(apiKey *domain.APIKey) error {
 }
 
 // GetAPIKey 根据ID获取API Key
-func (s *Store) GetAPIKey(id string) (*domain.APIKey, error) {
+func (s *Store) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -564,7 +1089,7 @@ func (s *Store) GetAPIKey(id string) (*domain.APIKey, error) {
 }
 
 // GetAPIKeyByKey 根据Key字符串获取API Key
-func (s *Store) GetAPIKeyByKey(key string) (*domain.APIKey, error) {
+func (s *Store) GetAPIKeyByKey(ctx context.Context, key string) (*domain.APIKey, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -585,7 +1110,7 @@ func (s *Store) GetAPIKeyByKey(key string) (*domain.APIKey, error) {
 }
 
 // ListAPIKeysByUserID 列出用户的所有API Key
-func (s *Store) ListAPIKeysByUserID(userID string) ([]*domain.APIKey, error) {
+func (s *Store) ListAPIKeysByUserID(ctx context.Context, userID string) ([]*domain.APIKey, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -600,7 +1125,7 @@ func (s *Store) ListAPIKeysByUserID(userID string) ([]*domain.APIKey, error) {
 }
 
 // DeleteAPIKey 删除API Key
-func (s *Store) DeleteAPIKey(id string) error {
+func (s *Store) DeleteAPIKey(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -618,7 +1143,7 @@ func (s *Store) DeleteAPIKey(id string) error {
 }
 
 // UpdateAPIKeyLastUsed 更新API Key最后使用时间
-func (s *Store) UpdateAPIKeyLastUsed(id string) error {
+func (s *Store) UpdateAPIKeyLastUsed(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -636,7 +1161,7 @@ func (s *Store) UpdateAPIKeyLastUsed(id string) error {
 // ========== Admin Repository ==========
 
 // ListUsers 列出用户（支持分页和过滤）
-func (s *Store) ListUsers(page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) {
+func (s *Store) ListUsers(ctx context.Context, page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -684,7 +1209,7 @@ func (s *Store) ListUsers(page, pageSize int, search string, role *domain.UserRo
 }
 
 // DeleteUser 删除用户
-func (s *Store) DeleteUser(userID string) error {
+func (s *Store) DeleteUser(ctx context.Context, userID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -696,12 +1221,15 @@ func (s *Store) DeleteUser(userID string) error {
 	// 删除用户
 	delete(s.users, userID)
 	delete(s.byEmail, user.Email)
+	if id, ok := s.byUsername[strings.ToLower(user.Username)]; ok && id == userID {
+		delete(s.byUsername, strings.ToLower(user.Username))
+	}
 
 	return nil
 }
 
 // DeleteMailboxesByUserID 删除用户的所有邮箱
-func (s *Store) DeleteMailboxesByUserID(userID string) error {
+func (s *Store) DeleteMailboxesByUserID(ctx context.Context, userID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -720,7 +1248,7 @@ func (s *Store) DeleteMailboxesByUserID(userID string) error {
 }
 
 // GetSystemStatistics 获取系统统计信息
-func (s *Store) GetSystemStatistics() (*domain.SystemStatistics, error) {
+func (s *Store) GetSystemStatistics(ctx context.Context) (*domain.SystemStatistics, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -770,7 +1298,7 @@ func (s *Store) GetSystemStatistics() (*domain.SystemStatistics, error) {
 }
 
 // GetDomainStatistics 获取域名统计信息
-func (s *Store) GetDomainStatistics(domain string) (mailboxCount, messageCount int, err error) {
+func (s *Store) GetDomainStatistics(ctx context.Context, domain string) (mailboxCount, messageCount int, err error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -799,7 +1327,7 @@ func containsIgnoreCase(s, substr string) bool {
 // ========== System Config Repository ==========
 
 // GetSystemConfig 获取系统配置
-func (s *Store) GetSystemConfig() (*domain.SystemConfig, error) {
+func (s *Store) GetSystemConfig(ctx context.Context) (*domain.SystemConfig, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -813,7 +1341,7 @@ func (s *Store) GetSystemConfig() (*domain.SystemConfig, error) {
 }
 
 // SaveSystemConfig 保存系统配置
-func (s *Store) SaveSystemConfig(config *domain.SystemConfig) error {
+func (s *Store) SaveSystemConfig(ctx context.Context, config *domain.SystemConfig) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -825,7 +1353,7 @@ func (s *Store) SaveSystemConfig(config *domain.SystemConfig) error {
 // ========== Alias Repository ==========
 
 // SaveAlias 保存邮箱别名
-func (s *Store) SaveAlias(alias *domain.MailboxAlias) error {
+func (s *Store) SaveAlias(ctx context.Context, alias *domain.MailboxAlias) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -836,7 +1364,7 @@ func (s *Store) SaveAlias(alias *domain.MailboxAlias) error {
 
 	// 检查别名地址是否已被使用（作为主地址或其他别名）
 	if _, ok := s.byAddress[alias.Address]; ok {
-		return errors.New("address already exists as mailbox")
+		return storage.ErrAddressTaken
 	}
 	// 检查地址是否被其他别名使用（更新同一别名时允许）
 	if existingID, ok := s.byAlias[alias.Address]; ok && existingID != alias.ID {
@@ -850,7 +1378,7 @@ func (s *Store) SaveAlias(alias *domain.MailboxAlias) error {
 }
 
 // GetAlias 根据ID获取别名
-func (s *Store) GetAlias(aliasID string) (*domain.MailboxAlias, error) {
+func (s *Store) GetAlias(ctx context.Context, aliasID string) (*domain.MailboxAlias, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -863,7 +1391,7 @@ func (s *Store) GetAlias(aliasID string) (*domain.MailboxAlias, error) {
 }
 
 // GetAliasByAddress 根据地址获取别名
-func (s *Store) GetAliasByAddress(address string) (*domain.MailboxAlias, error) {
+func (s *Store) GetAliasByAddress(ctx context.Context, address string) (*domain.MailboxAlias, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -881,7 +1409,7 @@ func (s *Store) GetAliasByAddress(address string) (*domain.MailboxAlias, error)
 }
 
 // ListAliasesByMailboxID 列出指定邮箱的所有别名
-func (s *Store) ListAliasesByMailboxID(mailboxID string) ([]*domain.MailboxAlias, error) {
+func (s *Store) ListAliasesByMailboxID(ctx context.Context, mailboxID string) ([]*domain.MailboxAlias, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -896,7 +1424,7 @@ func (s *Store) ListAliasesByMailboxID(mailboxID string) ([]*domain.MailboxAlias
 }
 
 // DeleteAlias 删除别名
-func (s *Store) DeleteAlias(aliasID string) error {
+func (s *Store) DeleteAlias(ctx context.Context, aliasID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -914,21 +1442,34 @@ func (s *Store) DeleteAlias(aliasID string) error {
 // ========== JWT 黑名单 ==========
 
 // AddToBlacklist 将 JWT 添加到黑名单
-func (s *Store) AddToBlacklist(jti string, ttl time.Duration) error {
-	// 内存存储不支持 JWT 黑名单，返回错误
-	return errors.New("JWT blacklist not supported in memory storage")
+func (s *Store) AddToBlacklist(ctx context.Context, jti string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blacklist[jti] = time.Now().Add(ttl)
+	return nil
 }
 
 // IsBlacklisted 检查 JWT 是否在黑名单中
-func (s *Store) IsBlacklisted(jti string) (bool, error) {
-	// 内存存储不支持 JWT 黑名单，总是返回 false
-	return false, nil
+func (s *Store) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.blacklist[jti]
+	if !ok {
+		return false, nil
+	}
+	if time.Now().After(expiresAt) {
+		delete(s.blacklist, jti)
+		return false, nil
+	}
+	return true, nil
 }
 
 // ========== 限流 ==========
 
 // IncrementRateLimit 增加限流计数
-func (s *Store) IncrementRateLimit(key string, window time.Duration) (int64, error) {
+func (s *Store) IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -962,7 +1503,7 @@ func (s *Store) IncrementRateLimit(key string, window time.Duration) (int64, err
 }
 
 // GetRateLimit 获取限流计数
-func (s *Store) GetRateLimit(key string) (int64, error) {
+func (s *Store) GetRateLimit(ctx context.Context, key string) (int64, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -974,38 +1515,63 @@ func (s *Store) GetRateLimit(key string) (int64, error) {
 	return entry.Count, nil
 }
 
+// ResetRateLimit 清零限流计数
+func (s *Store) ResetRateLimit(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.rateLimits, key)
+	return nil
+}
+
 // ========== 会话管理 ==========
 
 // CacheSession 缓存用户会话
-func (s *Store) CacheSession(sessionID string, userID string, ttl time.Duration) error {
-	// 内存存储不支持会话缓存，返回错误
-	return errors.New("session caching not supported in memory storage")
+func (s *Store) CacheSession(ctx context.Context, sessionID string, userID string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[sessionID] = &sessionEntry{UserID: userID, ExpiresAt: time.Now().Add(ttl)}
+	return nil
 }
 
 // GetCachedSession 获取缓存的会话
-func (s *Store) GetCachedSession(sessionID string) (string, error) {
-	// 内存存储不支持会话缓存，返回错误
-	return "", errors.New("session caching not supported in memory storage")
+func (s *Store) GetCachedSession(ctx context.Context, sessionID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.sessions[sessionID]
+	if !ok {
+		return "", errors.New("session not found")
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		delete(s.sessions, sessionID)
+		return "", errors.New("session not found")
+	}
+	return entry.UserID, nil
 }
 
 // DeleteCachedSession 删除缓存的会话
-func (s *Store) DeleteCachedSession(sessionID string) error {
-	// 内存存储不支持会话缓存，返回错误
-	return errors.New("session caching not supported in memory storage")
+func (s *Store) DeleteCachedSession(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sessionID)
+	return nil
 }
 
 // ========== 发布订阅 ==========
 
 // PublishNewMail 发布新邮件通知
-func (s *Store) PublishNewMail(mailboxID string, message *domain.Message) error {
-	// 内存存储不支持发布订阅，返回错误
-	return errors.New("pub/sub not supported in memory storage")
+func (s *Store) PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error {
+	// 单实例部署没有其他订阅者，新邮件由 WebSocket Hub 直接推送
+	return nil
 }
 
 // SubscribeNewMail 订阅新邮件通知
-func (s *Store) SubscribeNewMail(mailboxID string) interface{} {
-	// 内存存储不支持发布订阅，返回 nil
-	return nil
+func (s *Store) SubscribeNewMail(ctx context.Context) (<-chan *domain.Message, error) {
+	// 内存存储不支持发布订阅
+	return nil, storage.ErrPubSubUnavailable
 }
 
 // ========== 工具方法 ==========
@@ -25,30 +25,30 @@ func TestMemoryStore_MailboxOperations(t *testing.T) {
 		CreatedAt: time.Now(),
 	}
 
-	err := store.SaveMailbox(mailbox)
+	err := store.SaveMailbox(t.Context(), mailbox)
 	require.NoError(t, err)
 
 	// Test GetMailbox
-	retrievedMailbox, err := store.GetMailbox("test-mailbox-1")
+	retrievedMailbox, err := store.GetMailbox(t.Context(), "test-mailbox-1")
 	require.NoError(t, err)
 	assert.Equal(t, mailbox.Address, retrievedMailbox.Address)
 	assert.Equal(t, mailbox.Domain, retrievedMailbox.Domain)
 
 	// Test GetMailboxByAddress
-	retrievedMailbox, err = store.GetMailboxByAddress("test@temp.mail")
+	retrievedMailbox, err = store.GetMailboxByAddress(t.Context(), "test@temp.mail")
 	require.NoError(t, err)
 	assert.Equal(t, mailbox.ID, retrievedMailbox.ID)
 
 	// Test ListMailboxesByUserID
-	mailboxes := store.ListMailboxesByUserID("test-user-1")
+	mailboxes := store.ListMailboxesByUserID(t.Context(), "test-user-1")
 	assert.Len(t, mailboxes, 1)
 	assert.Equal(t, mailbox.ID, mailboxes[0].ID)
 
 	// Test DeleteMailbox
-	err = store.DeleteMailbox("test-mailbox-1")
+	err = store.DeleteMailbox(t.Context(), "test-mailbox-1")
 	require.NoError(t, err)
 
-	_, err = store.GetMailbox("test-mailbox-1")
+	_, err = store.GetMailbox(t.Context(), "test-mailbox-1")
 	assert.Error(t, err)
 }
 
@@ -66,7 +66,7 @@ func TestMemoryStore_MessageOperations(t *testing.T) {
 		Token:     "test-token",
 		CreatedAt: time.Now(),
 	}
-	err := store.SaveMailbox(mailbox)
+	err := store.SaveMailbox(t.Context(), mailbox)
 	require.NoError(t, err)
 
 	// Test SaveMessage
@@ -80,26 +80,26 @@ func TestMemoryStore_MessageOperations(t *testing.T) {
 		CreatedAt: time.Now(),
 	}
 
-	err = store.SaveMessage(message)
+	err = store.SaveMessage(t.Context(), message)
 	require.NoError(t, err)
 
 	// Test GetMessage
-	retrievedMessage, err := store.GetMessage("test-mailbox-1", "test-message-1")
+	retrievedMessage, err := store.GetMessage(t.Context(), "test-mailbox-1", "test-message-1")
 	require.NoError(t, err)
 	assert.Equal(t, message.Subject, retrievedMessage.Subject)
 	assert.Equal(t, message.From, retrievedMessage.From)
 
 	// Test ListMessages
-	messages, err := store.ListMessages("test-mailbox-1")
+	messages, err := store.ListMessages(t.Context(), "test-mailbox-1")
 	require.NoError(t, err)
 	assert.Len(t, messages, 1)
 	assert.Equal(t, message.ID, messages[0].ID)
 
 	// Test MarkMessageRead
-	err = store.MarkMessageRead("test-mailbox-1", "test-message-1")
+	err = store.MarkMessageRead(t.Context(), "test-mailbox-1", "test-message-1")
 	require.NoError(t, err)
 
-	retrievedMessage, err = store.GetMessage("test-mailbox-1", "test-message-1")
+	retrievedMessage, err = store.GetMessage(t.Context(), "test-mailbox-1", "test-message-1")
 	require.NoError(t, err)
 	assert.True(t, retrievedMessage.IsRead)
 }
@@ -121,15 +121,81 @@ func TestMemoryStore_Cleanup(t *testing.T) {
 		ExpiresAt: &expiresAt,
 	}
 
-	err := store.SaveMailbox(mailbox)
+	err := store.SaveMailbox(t.Context(), mailbox)
 	require.NoError(t, err)
 
 	// Run cleanup
-	count, err := store.DeleteExpiredMailboxes()
+	count, err := store.DeleteExpiredMailboxes(t.Context())
 	require.NoError(t, err)
 	assert.Equal(t, 1, count)
 
 	// Verify mailbox is deleted
-	_, err = store.GetMailbox("test-mailbox-1")
+	_, err = store.GetMailbox(t.Context(), "test-mailbox-1")
 	assert.Error(t, err)
-}
\ No newline at end of file
+}
+func TestMemoryStore_BlacklistAndSessions(t *testing.T) {
+	store := NewStore(time.Hour)
+
+	require.NoError(t, store.AddToBlacklist(t.Context(), "jti-live", time.Hour))
+	require.NoError(t, store.AddToBlacklist(t.Context(), "jti-expired", -time.Second))
+
+	blacklisted, err := store.IsBlacklisted(t.Context(), "jti-live")
+	require.NoError(t, err)
+	assert.True(t, blacklisted)
+	blacklisted, err = store.IsBlacklisted(t.Context(), "jti-expired")
+	require.NoError(t, err)
+	assert.False(t, blacklisted)
+
+	require.NoError(t, store.CacheSession(t.Context(), "session-1", "user-1", time.Hour))
+	userID, err := store.GetCachedSession(t.Context(), "session-1")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+
+	require.NoError(t, store.DeleteCachedSession(t.Context(), "session-1"))
+	_, err = store.GetCachedSession(t.Context(), "session-1")
+	assert.Error(t, err)
+}
+
+func TestMemoryStore_MailboxSummaries(t *testing.T) {
+	store := NewStore(24 * time.Hour)
+	userID, otherID := "user-1", "user-2"
+	created := time.Now().Add(-time.Hour)
+	for _, mb := range []*domain.Mailbox{
+		{ID: "mb-active", Address: "active@temp.mail", UserID: &userID, CreatedAt: created},
+		{ID: "mb-empty", Address: "empty@temp.mail", UserID: &userID, CreatedAt: created},
+		{ID: "mb-other", Address: "other@temp.mail", UserID: &otherID, CreatedAt: created},
+	} {
+		require.NoError(t, store.SaveMailbox(t.Context(), mb))
+	}
+
+	for i, id := range []string{"m1", "m2", "m3", "m4"} {
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+			ID: id, MailboxID: "mb-active", From: "sender@example.com", Subject: id, Size: 10,
+			ReceivedAt: created.Add(time.Duration(i+1) * time.Minute),
+		}))
+	}
+	require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{ID: "spam", MailboxID: "mb-active", Size: 5, Quarantined: true, ReceivedAt: time.Now()}))
+	require.NoError(t, store.MarkMessageRead(t.Context(), "mb-active", "m1"))
+	require.NoError(t, store.DeleteMessage(t.Context(), "mb-active", "m2"))
+	require.NoError(t, store.DeleteMessage(t.Context(), "mb-active", "m4"))
+
+	summaries, err := store.ListMailboxSummariesByUserID(t.Context(), userID)
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	byID := map[string]domain.MailboxSummary{}
+	for _, summary := range summaries {
+		byID[summary.ID] = summary
+	}
+
+	active := byID["mb-active"]
+	assert.Equal(t, 2, active.TotalCount)
+	assert.Equal(t, 1, active.Unread)
+	assert.Equal(t, int64(25), active.StorageBytes)
+	require.NotNil(t, active.LastMessage)
+	assert.Equal(t, "m3", active.LastMessage.ID)
+	assert.Equal(t, created.Add(3*time.Minute), active.LastActivityAt())
+
+	assert.Zero(t, byID["mb-empty"].TotalCount)
+	assert.Nil(t, byID["mb-empty"].LastMessage)
+	assert.Equal(t, created, byID["mb-empty"].LastActivityAt())
+}
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -13,7 +14,7 @@ var (
 )
 
 // SaveSystemDomain 保存系统域名
-func (s *Store) SaveSystemDomain(sysDomain *domain.SystemDomain) error {
+func (s *Store) SaveSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -29,7 +30,7 @@ func (s *Store) SaveSystemDomain(sysDomain *domain.SystemDomain) error {
 }
 
 // GetSystemDomain 根据 ID 获取系统域名
-func (s *Store) GetSystemDomain(id string) (*domain.SystemDomain, error) {
+func (s *Store) GetSystemDomain(ctx context.Context, id string) (*domain.SystemDomain, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -42,7 +43,7 @@ func (s *Store) GetSystemDomain(id string) (*domain.SystemDomain, error) {
 }
 
 // GetSystemDomainByDomain 根据域名获取系统域名
-func (s *Store) GetSystemDomainByDomain(domainName string) (*domain.SystemDomain, error) {
+func (s *Store) GetSystemDomainByDomain(ctx context.Context, domainName string) (*domain.SystemDomain, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -60,7 +61,7 @@ func (s *Store) GetSystemDomainByDomain(domainName string) (*domain.SystemDomain
 }
 
 // ListSystemDomains 获取所有系统域名
-func (s *Store) ListSystemDomains() ([]*domain.SystemDomain, error) {
+func (s *Store) ListSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -73,7 +74,7 @@ func (s *Store) ListSystemDomains() ([]*domain.SystemDomain, error) {
 }
 
 // ListActiveSystemDomains 获取所有已激活的系统域名
-func (s *Store) ListActiveSystemDomains() ([]*domain.SystemDomain, error) {
+func (s *Store) ListActiveSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -88,7 +89,7 @@ func (s *Store) ListActiveSystemDomains() ([]*domain.SystemDomain, error) {
 }
 
 // DeleteSystemDomain 删除系统域名
-func (s *Store) DeleteSystemDomain(id string) error {
+func (s *Store) DeleteSystemDomain(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -104,7 +105,7 @@ func (s *Store) DeleteSystemDomain(id string) error {
 }
 
 // IncrementSystemDomainMailboxCount 增加系统域名邮箱计数
-func (s *Store) IncrementSystemDomainMailboxCount(domainName string) error {
+func (s *Store) IncrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -123,7 +124,7 @@ func (s *Store) IncrementSystemDomainMailboxCount(domainName string) error {
 }
 
 // DecrementSystemDomainMailboxCount 减少系统域名邮箱计数
-func (s *Store) DecrementSystemDomainMailboxCount(domainName string) error {
+func (s *Store) DecrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -142,7 +143,7 @@ func (s *Store) DecrementSystemDomainMailboxCount(domainName string) error {
 }
 
 // DeleteUnverifiedSystemDomains 删除指定时间前创建且未验证的域名
-func (s *Store) DeleteUnverifiedSystemDomains(before time.Time) (int, error) {
+func (s *Store) DeleteUnverifiedSystemDomains(ctx context.Context, before time.Time) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
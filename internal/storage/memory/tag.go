@@ -1,14 +1,16 @@
 package memory
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"tempmail/backend/internal/domain"
 )
 
 // CreateTag 创建标签
-func (s *Store) CreateTag(tag *domain.Tag) error {
+func (s *Store) CreateTag(ctx context.Context, tag *domain.Tag) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -33,7 +35,7 @@ func (s *Store) CreateTag(tag *domain.Tag) error {
 }
 
 // GetTag 获取标签
-func (s *Store) GetTag(id string) (*domain.Tag, error) {
+func (s *Store) GetTag(ctx context.Context, id string) (*domain.Tag, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -46,7 +48,7 @@ func (s *Store) GetTag(id string) (*domain.Tag, error) {
 }
 
 // GetTagByName 根据名称获取标签
-func (s *Store) GetTagByName(userID, name string) (*domain.Tag, error) {
+func (s *Store) GetTagByName(ctx context.Context, userID, name string) (*domain.Tag, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -65,7 +67,7 @@ func (s *Store) GetTagByName(userID, name string) (*domain.Tag, error) {
 }
 
 // ListTags 列出用户的所有标签
-func (s *Store) ListTags(userID string) ([]domain.TagWithCount, error) {
+func (s *Store) ListTags(ctx context.Context, userID string) ([]domain.TagWithCount, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -93,8 +95,33 @@ func (s *Store) ListTags(userID string) ([]domain.TagWithCount, error) {
 	return result, nil
 }
 
+// ListTagsByOrgID 列出组织的所有标签
+func (s *Store) ListTagsByOrgID(ctx context.Context, orgID string) ([]domain.TagWithCount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.TagWithCount, 0)
+	for _, tag := range s.tags {
+		if tag.OrgID == nil || *tag.OrgID != orgID {
+			continue
+		}
+		count := 0
+		for _, mt := range s.messageTags {
+			if mt.TagID == tag.ID {
+				count++
+			}
+		}
+		result = append(result, domain.TagWithCount{
+			Tag:          *tag,
+			MessageCount: count,
+		})
+	}
+
+	return result, nil
+}
+
 // UpdateTag 更新标签
-func (s *Store) UpdateTag(tag *domain.Tag) error {
+func (s *Store) UpdateTag(ctx context.Context, tag *domain.Tag) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -119,7 +146,7 @@ func (s *Store) UpdateTag(tag *domain.Tag) error {
 }
 
 // DeleteTag 删除标签
-func (s *Store) DeleteTag(id string) error {
+func (s *Store) DeleteTag(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -145,7 +172,7 @@ func (s *Store) DeleteTag(id string) error {
 }
 
 // AddMessageTag 为邮件添加标签
-func (s *Store) AddMessageTag(messageID, tagID string) error {
+func (s *Store) AddMessageTag(ctx context.Context, messageID, tagID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -178,7 +205,7 @@ func (s *Store) AddMessageTag(messageID, tagID string) error {
 }
 
 // RemoveMessageTag 移除邮件标签
-func (s *Store) RemoveMessageTag(messageID, tagID string) error {
+func (s *Store) RemoveMessageTag(ctx context.Context, messageID, tagID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -192,8 +219,16 @@ func (s *Store) RemoveMessageTag(messageID, tagID string) error {
 	return nil
 }
 
+// deleteMessageTagsLocked 删除邮件的所有标签关联（调用方持有写锁）
+func (s *Store) deleteMessageTagsLocked(messageID string) {
+	for tagID := range s.tagsByMessage[messageID] {
+		delete(s.messageTags, messageID+":"+tagID)
+	}
+	delete(s.tagsByMessage, messageID)
+}
+
 // GetMessageTags 获取邮件的所有标签
-func (s *Store) GetMessageTags(messageID string) ([]domain.Tag, error) {
+func (s *Store) GetMessageTags(ctx context.Context, messageID string) ([]domain.Tag, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -213,7 +248,7 @@ func (s *Store) GetMessageTags(messageID string) ([]domain.Tag, error) {
 }
 
 // ListMessagesByTag 列出标签下的所有邮件
-func (s *Store) ListMessagesByTag(tagID string) ([]domain.Message, error) {
+func (s *Store) ListMessagesByTag(ctx context.Context, tagID string) ([]domain.Message, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -234,12 +269,19 @@ func (s *Store) ListMessagesByTag(tagID string) ([]domain.Message, error) {
 			}
 		}
 	}
+	// 跨邮箱时按创建时间倒序，同一时刻按序号
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].Seq > result[j].Seq
+	})
 
 	return result, nil
 }
 
 // DeleteMessageTags 删除邮件的所有标签
-func (s *Store) DeleteMessageTags(messageID string) error {
+func (s *Store) DeleteMessageTags(ctx context.Context, messageID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
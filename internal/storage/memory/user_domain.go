@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"context"
 	"errors"
 
 	"tempmail/backend/internal/domain"
@@ -14,7 +15,7 @@ var (
 )
 
 // SaveUserDomain 保存用户域名
-func (s *Store) SaveUserDomain(domain *domain.UserDomain) error {
+func (s *Store) SaveUserDomain(ctx context.Context, domain *domain.UserDomain) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -30,7 +31,7 @@ func (s *Store) SaveUserDomain(domain *domain.UserDomain) error {
 }
 
 // GetUserDomain 根据 ID 获取用户域名
-func (s *Store) GetUserDomain(id string) (*domain.UserDomain, error) {
+func (s *Store) GetUserDomain(ctx context.Context, id string) (*domain.UserDomain, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -43,7 +44,7 @@ func (s *Store) GetUserDomain(id string) (*domain.UserDomain, error) {
 }
 
 // GetUserDomainByDomain 根据域名获取
-func (s *Store) GetUserDomainByDomain(domainName string) (*domain.UserDomain, error) {
+func (s *Store) GetUserDomainByDomain(ctx context.Context, domainName string) (*domain.UserDomain, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -61,7 +62,7 @@ func (s *Store) GetUserDomainByDomain(domainName string) (*domain.UserDomain, er
 }
 
 // ListUserDomainsByUserID 获取用户的所有域名
-func (s *Store) ListUserDomainsByUserID(userID string) ([]*domain.UserDomain, error) {
+func (s *Store) ListUserDomainsByUserID(ctx context.Context, userID string) ([]*domain.UserDomain, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -75,8 +76,23 @@ func (s *Store) ListUserDomainsByUserID(userID string) ([]*domain.UserDomain, er
 	return result, nil
 }
 
+// ListUserDomainsByOrgID 获取组织的所有域名
+func (s *Store) ListUserDomainsByOrgID(ctx context.Context, orgID string) ([]*domain.UserDomain, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*domain.UserDomain, 0)
+	for _, d := range s.userDomains {
+		if d.OrgID != nil && *d.OrgID == orgID {
+			result = append(result, d)
+		}
+	}
+
+	return result, nil
+}
+
 // ListAllUserDomains 获取所有用户域名
-func (s *Store) ListAllUserDomains() ([]*domain.UserDomain, error) {
+func (s *Store) ListAllUserDomains(ctx context.Context) ([]*domain.UserDomain, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -89,7 +105,7 @@ func (s *Store) ListAllUserDomains() ([]*domain.UserDomain, error) {
 }
 
 // UpdateUserDomain 更新用户域名
-func (s *Store) UpdateUserDomain(userDomain *domain.UserDomain) error {
+func (s *Store) UpdateUserDomain(ctx context.Context, userDomain *domain.UserDomain) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -98,7 +114,7 @@ func (s *Store) UpdateUserDomain(userDomain *domain.UserDomain) error {
 }
 
 // DeleteUserDomain 删除用户域名
-func (s *Store) DeleteUserDomain(id string) error {
+func (s *Store) DeleteUserDomain(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -109,12 +125,17 @@ func (s *Store) DeleteUserDomain(id string) error {
 
 	delete(s.userDomains, id)
 	delete(s.byDomain, domain.Domain)
+	for entryID, entry := range s.domainWhitelist {
+		if entry.DomainID == id {
+			delete(s.domainWhitelist, entryID)
+		}
+	}
 
 	return nil
 }
 
 // IncrementMailboxCount 增加邮箱计数
-func (s *Store) IncrementMailboxCount(domainName string) error {
+func (s *Store) IncrementMailboxCount(ctx context.Context, domainName string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -133,7 +154,7 @@ func (s *Store) IncrementMailboxCount(domainName string) error {
 }
 
 // DecrementMailboxCount 减少邮箱计数
-func (s *Store) DecrementMailboxCount(domainName string) error {
+func (s *Store) DecrementMailboxCount(ctx context.Context, domainName string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
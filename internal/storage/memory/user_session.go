@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// CreateUserSession 创建登录会话
+func (s *Store) CreateUserSession(ctx context.Context, session *domain.UserSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *session
+	s.userSessions[session.ID] = &copied
+	return nil
+}
+
+// GetUserSession 按ID获取登录会话
+func (s *Store) GetUserSession(ctx context.Context, id string) (*domain.UserSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.userSessions[id]
+	if !ok {
+		return nil, storage.ErrUserSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+// RotateUserSession 比较刷新令牌 jti 后保存轮换后的会话
+func (s *Store) RotateUserSession(ctx context.Context, session *domain.UserSession, previousRefreshID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.userSessions[session.ID]
+	if !ok {
+		return storage.ErrUserSessionNotFound
+	}
+	if existing.RefreshTokenID != previousRefreshID {
+		return storage.ErrUserSessionRotated
+	}
+	copied := *session
+	s.userSessions[session.ID] = &copied
+	return nil
+}
+
+// ListUserSessions 列出用户的会话（按最近使用时间倒序）
+func (s *Store) ListUserSessions(ctx context.Context, userID string) ([]*domain.UserSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sessions []*domain.UserSession
+	for _, session := range s.userSessions {
+		if session.UserID == userID {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+// DeleteUserSession 删除登录会话
+func (s *Store) DeleteUserSession(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.userSessions[id]; !ok {
+		return storage.ErrUserSessionNotFound
+	}
+	delete(s.userSessions, id)
+	return nil
+}
+
+// DeleteExpiredUserSessions 删除已过期的登录会话
+func (s *Store) DeleteExpiredUserSessions(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for id, session := range s.userSessions {
+		if !session.ExpiresAt.After(now) {
+			delete(s.userSessions, id)
+			count++
+		}
+	}
+	return count, nil
+}
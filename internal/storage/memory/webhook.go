@@ -1,14 +1,16 @@
 package memory
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"tempmail/backend/internal/domain"
 )
 
 // CreateWebhook 创建 Webhook
-func (s *Store) CreateWebhook(webhook *domain.Webhook) error {
+func (s *Store) CreateWebhook(ctx context.Context, webhook *domain.Webhook) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -19,19 +21,31 @@ func (s *Store) CreateWebhook(webhook *domain.Webhook) error {
 
 	webhook.CreatedAt = time.Now()
 	webhook.UpdatedAt = time.Now()
-	s.webhooks[webhook.ID] = webhook
+	s.indexWebhookLocked(webhook)
 
-	// 按用户ID索引
+	return nil
+}
+
+// indexWebhookLocked 保存 Webhook 并按用户ID索引（邮箱 Webhook 不属于任何用户，不进入用户索引）
+func (s *Store) indexWebhookLocked(webhook *domain.Webhook) {
+	if webhook.OwnerType == "" {
+		webhook.OwnerType = domain.WebhookOwnerUser // 与数据库列默认值一致
+	}
+	if webhook.PayloadFormat == "" {
+		webhook.PayloadFormat = domain.WebhookPayloadHeaders
+	}
+	s.webhooks[webhook.ID] = webhook
+	if webhook.MailboxScoped() {
+		return
+	}
 	if s.webhooksByUser[webhook.UserID] == nil {
 		s.webhooksByUser[webhook.UserID] = make(map[string]*domain.Webhook)
 	}
 	s.webhooksByUser[webhook.UserID][webhook.ID] = webhook
-
-	return nil
 }
 
 // GetWebhook 获取 Webhook
-func (s *Store) GetWebhook(id string) (*domain.Webhook, error) {
+func (s *Store) GetWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -44,7 +58,7 @@ func (s *Store) GetWebhook(id string) (*domain.Webhook, error) {
 }
 
 // ListWebhooks 列出用户的 Webhooks
-func (s *Store) ListWebhooks(userID string) ([]domain.Webhook, error) {
+func (s *Store) ListWebhooks(ctx context.Context, userID string) ([]domain.Webhook, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -61,8 +75,49 @@ func (s *Store) ListWebhooks(userID string) ([]domain.Webhook, error) {
 	return result, nil
 }
 
+// ListWebhooksByOrgID 列出组织的所有 Webhook
+func (s *Store) ListWebhooksByOrgID(ctx context.Context, orgID string) ([]domain.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.Webhook, 0)
+	for _, webhook := range s.webhooks {
+		if webhook.OrgID != nil && *webhook.OrgID == orgID {
+			result = append(result, *webhook)
+		}
+	}
+
+	return result, nil
+}
+
+// ListWebhooksByMailbox 列出邮箱 Webhooks
+func (s *Store) ListWebhooksByMailbox(ctx context.Context, mailboxID string) ([]domain.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.Webhook, 0)
+	for _, webhook := range s.webhooks {
+		if webhook.MailboxScoped() && webhook.OwnerID == mailboxID {
+			result = append(result, *webhook)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+
+	return result, nil
+}
+
+// deleteMailboxWebhooksLocked 删除邮箱 Webhooks 及其投递记录（随邮箱删除，调用方持有写锁）
+func (s *Store) deleteMailboxWebhooksLocked(mailboxID string) {
+	for id, webhook := range s.webhooks {
+		if webhook.MailboxScoped() && webhook.OwnerID == mailboxID {
+			delete(s.webhooks, id)
+			delete(s.deliveries, id)
+		}
+	}
+}
+
 // UpdateWebhook 更新 Webhook
-func (s *Store) UpdateWebhook(webhook *domain.Webhook) error {
+func (s *Store) UpdateWebhook(ctx context.Context, webhook *domain.Webhook) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -73,14 +128,13 @@ func (s *Store) UpdateWebhook(webhook *domain.Webhook) error {
 
 	webhook.UpdatedAt = time.Now()
 	webhook.CreatedAt = existing.CreatedAt
-	s.webhooks[webhook.ID] = webhook
-	s.webhooksByUser[webhook.UserID][webhook.ID] = webhook
+	s.indexWebhookLocked(webhook)
 
 	return nil
 }
 
 // DeleteWebhook 删除 Webhook
-func (s *Store) DeleteWebhook(id string) error {
+func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -90,13 +144,16 @@ func (s *Store) DeleteWebhook(id string) error {
 	}
 
 	delete(s.webhooks, id)
-	delete(s.webhooksByUser[webhook.UserID], id)
+	delete(s.deliveries, id)
+	if !webhook.MailboxScoped() {
+		delete(s.webhooksByUser[webhook.UserID], id)
+	}
 
 	return nil
 }
 
 // RecordDelivery 记录投递结果
-func (s *Store) RecordDelivery(delivery *domain.WebhookDelivery) error {
+func (s *Store) RecordDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -125,7 +182,8 @@ func (s *Store) RecordDelivery(delivery *domain.WebhookDelivery) error {
 			now := time.Now()
 			webhook.LastSuccess = &now
 			webhook.LastError = ""
-		} else {
+		} else if delivery.ErrorKind != domain.WebhookErrorKindCircuitOpen {
+			// 熔断短路的投递未连接接收方，不计入失败次数
 			webhook.RetryCount++
 			webhook.LastError = delivery.Error
 		}
@@ -136,7 +194,7 @@ func (s *Store) RecordDelivery(delivery *domain.WebhookDelivery) error {
 }
 
 // GetDeliveries 获取投递记录
-func (s *Store) GetDeliveries(webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+func (s *Store) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -160,7 +218,7 @@ func (s *Store) GetDeliveries(webhookID string, limit int) ([]domain.WebhookDeli
 }
 
 // GetPendingDeliveries 获取待重试的投递
-func (s *Store) GetPendingDeliveries(limit int) ([]domain.WebhookDelivery, error) {
+func (s *Store) GetPendingDeliveries(ctx context.Context, limit int) ([]domain.WebhookDelivery, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -185,3 +243,77 @@ func (s *Store) GetPendingDeliveries(limit int) ([]domain.WebhookDelivery, error
 	s.retryQueue = newQueue
 	return result, nil
 }
+
+// CancelPendingDeliveries 取消指定邮箱尚未完成的重试投递，返回取消数量
+func (s *Store) CancelPendingDeliveries(ctx context.Context, mailboxID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	newQueue := make([]*domain.WebhookDelivery, 0, len(s.retryQueue))
+	for _, delivery := range s.retryQueue {
+		if delivery.MailboxID == mailboxID {
+			delivery.NextRetry = nil
+			count++
+			continue
+		}
+		newQueue = append(newQueue, delivery)
+	}
+	s.retryQueue = newQueue
+	return count, nil
+}
+
+// GetDelivery 获取投递记录
+func (s *Store) GetDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, deliveries := range s.deliveries {
+		for _, delivery := range deliveries {
+			if delivery.ID == id {
+				copied := *delivery
+				return &copied, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("webhook delivery not found")
+}
+
+// UpdateDelivery 更新投递记录
+func (s *Store) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.deliveries[delivery.WebhookID] {
+		if existing.ID == delivery.ID {
+			*existing = *delivery
+			return nil
+		}
+	}
+	return fmt.Errorf("webhook delivery not found")
+}
+
+// ListDeadLetters 列出尚未重新投递的死信（webhookID 为空时列出全部，新的在前）
+func (s *Store) ListDeadLetters(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.WebhookDelivery, 0)
+	for id, deliveries := range s.deliveries {
+		if webhookID != "" && id != webhookID {
+			continue
+		}
+		for _, delivery := range deliveries {
+			if delivery.DeadLetter && delivery.RedeliveredAt == nil {
+				result = append(result, *delivery)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Abuse Report Repository ==========
+
+// SaveAbuseReport 保存滥用举报（覆盖已有记录）
+func (s *Store) SaveAbuseReport(ctx context.Context, report *domain.AbuseReport) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.upsert(ctx, bson.M{"_id": report.ID}, report)
+}
+
+// GetAbuseReport 获取滥用举报
+func (s *Store) GetAbuseReport(ctx context.Context, id string) (*domain.AbuseReport, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var report domain.AbuseReport
+	if err := s.findOne(ctx, &report, bson.M{"_id": id}, storage.ErrAbuseReportNotFound); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ListAbuseReports 按过滤条件列出举报（按创建时间倒序）
+func (s *Store) ListAbuseReports(ctx context.Context, filter domain.AbuseReportFilter) ([]*domain.AbuseReport, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Category != "" {
+		query["category"] = filter.Category
+	}
+	if filter.MailboxID != "" {
+		query["mailbox_id"] = filter.MailboxID
+	}
+	if filter.Priority {
+		query["priority"] = true
+	}
+
+	var reports []*domain.AbuseReport
+	if err := s.find(ctx, &reports, query, options.Find().SetSort(sortBy("created_at", -1, "_id", -1))); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
@@ -0,0 +1,66 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Analytics Repository ==========
+
+// MergeAnalyticsBuckets 合并统计计数
+//
+// 计数类指标 $inc 累加，峰值类指标 $max 取最大值；upsert 完成，多个实例同时写入同一时间桶时不会丢失计数。
+func (s *Store) MergeAnalyticsBuckets(ctx context.Context, buckets []domain.AnalyticsBucket) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, 0, len(buckets))
+	for _, bucket := range buckets {
+		merge := "$inc"
+		if domain.IsPeakAnalyticsMetric(bucket.Metric) {
+			merge = "$max"
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"metric": bucket.Metric, "dimension": bucket.Dimension, "bucket": bucket.Bucket.UTC()}).
+			SetUpdate(bson.M{merge: bson.M{"value": bucket.Value}}).
+			SetUpsert(true))
+	}
+	_, err := s.c(&domain.AnalyticsBucket{}).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// ListAnalyticsBuckets 列出指标在 [since, until) 内的时间桶
+func (s *Store) ListAnalyticsBuckets(ctx context.Context, metric string, since, until time.Time) ([]domain.AnalyticsBucket, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var buckets []domain.AnalyticsBucket
+	err := s.find(ctx, &buckets,
+		bson.M{"metric": metric, "bucket": bson.M{"$gte": since.UTC(), "$lt": until.UTC()}},
+		options.Find().SetSort(sortBy("bucket", 1, "dimension", 1)))
+	for i := range buckets {
+		buckets[i].Bucket = buckets[i].Bucket.UTC()
+	}
+	return buckets, err
+}
+
+// DeleteAnalyticsBucketsBefore 删除早于 before 的时间桶
+func (s *Store) DeleteAnalyticsBucketsBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	result, err := s.c(&domain.AnalyticsBucket{}).DeleteMany(ctx, bson.M{"bucket": bson.M{"$lt": before.UTC()}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
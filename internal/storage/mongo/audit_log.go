@@ -0,0 +1,65 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Audit Log Repository ==========
+
+// CreateAuditLog 追加审计记录
+func (s *Store) CreateAuditLog(ctx context.Context, log *domain.AuditLog) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.insert(ctx, log)
+}
+
+// ListAuditLogs 按过滤条件分页列出审计记录（按时间倒序）
+func (s *Store) ListAuditLogs(ctx context.Context, filter domain.AuditLogFilter) ([]*domain.AuditLog, int64, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	query := bson.M{}
+	if filter.ActorID != "" {
+		query["actor_id"] = filter.ActorID
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.TargetType != "" {
+		query["target_type"] = filter.TargetType
+	}
+	if filter.TargetID != "" {
+		query["target_id"] = filter.TargetID
+	}
+	if !filter.Since.IsZero() || !filter.Until.IsZero() {
+		created := bson.M{}
+		if !filter.Since.IsZero() {
+			created["$gte"] = filter.Since
+		}
+		if !filter.Until.IsZero() {
+			created["$lt"] = filter.Until
+		}
+		query["created_at"] = created
+	}
+
+	total, err := s.count(ctx, &domain.AuditLog{}, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(sortBy("created_at", -1, "_id", -1)).
+		SetSkip(int64(max(filter.Offset, 0)))
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	var logs []*domain.AuditLog
+	if err := s.find(ctx, &logs, query, opts); err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
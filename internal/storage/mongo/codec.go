@@ -0,0 +1,66 @@
+package mongo
+
+import (
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonoptions"
+	"gorm.io/gorm/schema"
+)
+
+// naming 与 SQL 存储相同的 GORM 命名规则：集合名即表名，字段名即列名
+var naming = schema.NamingStrategy{}
+
+// newRegistry 按 GORM 标签编解码领域模型，文档字段与 SQL 列同名，查询条件可直接沿用列名
+//
+// ID 字段映射为 _id；gorm:"-" 的字段（正文、附件等保存在文件系统的内容）不写入；
+// JSON 序列化列和嵌入设置（黑洞、通配收件）保存为子文档。
+func newRegistry() (*bsoncodec.Registry, error) {
+	codec, err := bsoncodec.NewStructCodec(bsoncodec.StructTagParserFunc(parseGormTags),
+		bsonoptions.StructCodec().SetDecodeZeroStruct(true))
+	if err != nil {
+		return nil, err
+	}
+	registry := bson.NewRegistry()
+	registry.RegisterKindEncoder(reflect.Struct, codec)
+	registry.RegisterKindDecoder(reflect.Struct, codec)
+	return registry, nil
+}
+
+// parseGormTags 由 GORM 标签得到 BSON 字段名（聚合结果等带 bson 标签的辅助结构按 bson 标签解析）
+func parseGormTags(field reflect.StructField) (bsoncodec.StructTags, error) {
+	if _, ok := field.Tag.Lookup("bson"); ok {
+		return bsoncodec.DefaultStructTagParser(field)
+	}
+	settings := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")
+	if _, skip := settings["-"]; skip {
+		return bsoncodec.StructTags{Skip: true}, nil
+	}
+	// 匿名嵌入（如 TagWithCount 中的 Tag）与 GORM 一样展开到外层
+	if field.Anonymous && field.Type.Kind() == reflect.Struct {
+		return bsoncodec.StructTags{Inline: true}, nil
+	}
+
+	name := settings["COLUMN"]
+	if name == "" {
+		name = naming.ColumnName("", field.Name)
+	}
+	if name == "id" {
+		name = "_id"
+	}
+	return bsoncodec.StructTags{Name: strings.ToLower(name)}, nil
+}
+
+// collectionOf 模型（或模型切片）对应的集合名（与 SQL 表名一致）
+func collectionOf(model interface{}) string {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if tabler, ok := reflect.New(t).Interface().(schema.Tabler); ok {
+		return tabler.TableName()
+	}
+	return naming.TableName(t.Name())
+}
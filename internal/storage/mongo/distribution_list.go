@@ -0,0 +1,108 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Distribution List Repository ==========
+
+// SaveDistributionList 保存分发列表（地址唯一）
+func (s *Store) SaveDistributionList(ctx context.Context, list *domain.DistributionList) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	existing, err := s.count(ctx, list, bson.M{"address": list.Address, "_id": bson.M{"$ne": list.ID}})
+	if err != nil {
+		return err
+	}
+	if existing > 0 {
+		return storage.ErrDistributionListExists
+	}
+	if err := s.upsert(ctx, bson.M{"_id": list.ID}, list); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return storage.ErrDistributionListExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetDistributionList 获取分发列表
+func (s *Store) GetDistributionList(ctx context.Context, id string) (*domain.DistributionList, error) {
+	return s.getDistributionList(ctx, bson.M{"_id": id})
+}
+
+// GetDistributionListByAddress 根据地址获取分发列表
+func (s *Store) GetDistributionListByAddress(ctx context.Context, address string) (*domain.DistributionList, error) {
+	return s.getDistributionList(ctx, bson.M{"address": address})
+}
+
+// getDistributionList 按条件获取一个分发列表
+func (s *Store) getDistributionList(ctx context.Context, filter bson.M) (*domain.DistributionList, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var list domain.DistributionList
+	if err := s.findOne(ctx, &list, filter, storage.ErrDistributionListNotFound); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ListDistributionListsByUserID 列出用户创建的分发列表
+func (s *Store) ListDistributionListsByUserID(ctx context.Context, userID string) ([]*domain.DistributionList, error) {
+	return s.listDistributionLists(ctx, bson.M{"user_id": userID})
+}
+
+// ListDistributionListsByOrgID 列出组织的分发列表
+func (s *Store) ListDistributionListsByOrgID(ctx context.Context, orgID string) ([]*domain.DistributionList, error) {
+	return s.listDistributionLists(ctx, bson.M{"org_id": orgID})
+}
+
+// listDistributionLists 按创建时间列出分发列表
+func (s *Store) listDistributionLists(ctx context.Context, filter bson.M) ([]*domain.DistributionList, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var lists []*domain.DistributionList
+	err := s.find(ctx, &lists, filter, options.Find().SetSort(sortBy("created_at", 1)))
+	return lists, err
+}
+
+// DeleteDistributionList 删除分发列表及其投递报告
+func (s *Store) DeleteDistributionList(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.WithTransaction(func(tx *Store) error {
+		ctx := tx.bind(ctx)
+		if _, err := tx.c(&domain.DistributionListDelivery{}).DeleteMany(ctx, bson.M{"list_id": id}); err != nil {
+			return err
+		}
+		return tx.deleteOneContext(ctx, &domain.DistributionList{}, bson.M{"_id": id}, storage.ErrDistributionListNotFound)
+	})
+}
+
+// SaveDistributionListDelivery 记录分发列表投递报告
+func (s *Store) SaveDistributionListDelivery(ctx context.Context, delivery *domain.DistributionListDelivery) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.insert(ctx, delivery)
+}
+
+// ListDistributionListDeliveries 列出分发列表最近的投递报告（新的在前）
+func (s *Store) ListDistributionListDeliveries(ctx context.Context, listID string, limit int) ([]*domain.DistributionListDelivery, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	opts := options.Find().SetSort(sortBy("created_at", -1))
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	var deliveries []*domain.DistributionListDelivery
+	err := s.find(ctx, &deliveries, bson.M{"list_id": listID}, opts)
+	return deliveries, err
+}
@@ -0,0 +1,332 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// 域名的 mailbox_count 读取时按未过期邮箱实时统计：TTL 删除邮箱时没有回调，
+// 按增减维护的计数会越来越大。Increment/Decrement 因此为空操作。
+
+// mailboxCounts 按域名统计未过期邮箱数
+func (s *Store) mailboxCounts(ctx context.Context, domains []string) (map[string]int, error) {
+	counts := make(map[string]int, len(domains))
+	if len(domains) == 0 {
+		return counts, nil
+	}
+	cursor, err := s.c(&domain.Mailbox{}).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: live(bson.M{"domain": bson.M{"$in": domains}}, time.Now())}},
+		{{Key: "$group", Value: bson.M{"_id": "$domain", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Domain string `bson:"_id"`
+		Count  int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.Domain] = row.Count
+	}
+	return counts, nil
+}
+
+// fillSystemDomainCounts 填充系统域名的邮箱数
+func (s *Store) fillSystemDomainCounts(ctx context.Context, sysDomains ...*domain.SystemDomain) error {
+	names := make([]string, 0, len(sysDomains))
+	for _, sysDomain := range sysDomains {
+		names = append(names, sysDomain.Domain)
+	}
+	counts, err := s.mailboxCounts(ctx, names)
+	if err != nil {
+		return err
+	}
+	for _, sysDomain := range sysDomains {
+		sysDomain.MailboxCount = counts[sysDomain.Domain]
+	}
+	return nil
+}
+
+// fillUserDomainCounts 填充用户域名的邮箱数
+func (s *Store) fillUserDomainCounts(ctx context.Context, userDomains ...*domain.UserDomain) error {
+	names := make([]string, 0, len(userDomains))
+	for _, userDomain := range userDomains {
+		names = append(names, userDomain.Domain)
+	}
+	counts, err := s.mailboxCounts(ctx, names)
+	if err != nil {
+		return err
+	}
+	for _, userDomain := range userDomains {
+		userDomain.MailboxCount = counts[userDomain.Domain]
+	}
+	return nil
+}
+
+// ========== System Domain Repository ==========
+
+// SaveSystemDomain 保存系统域名
+func (s *Store) SaveSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	if sysDomain.ID == "" {
+		sysDomain.ID = uuid.New().String()
+	}
+
+	// 保留已有的创建时间（数据迁移）
+	if sysDomain.CreatedAt.IsZero() {
+		sysDomain.CreatedAt = time.Now().UTC()
+	}
+
+	return s.insert(ctx, sysDomain)
+}
+
+// GetSystemDomain 根据ID获取系统域名
+func (s *Store) GetSystemDomain(ctx context.Context, domainID string) (*domain.SystemDomain, error) {
+	return s.getSystemDomain(ctx, bson.M{"_id": domainID}, ErrSystemDomainNotFound)
+}
+
+// GetSystemDomainByDomain 根据域名获取系统域名
+func (s *Store) GetSystemDomainByDomain(ctx context.Context, domainName string) (*domain.SystemDomain, error) {
+	return s.getSystemDomain(ctx, bson.M{"domain": domainName}, ErrSystemDomainNotFound)
+}
+
+// GetDefaultSystemDomain 获取默认系统域名
+func (s *Store) GetDefaultSystemDomain(ctx context.Context) (*domain.SystemDomain, error) {
+	return s.getSystemDomain(ctx, bson.M{"is_default": true, "is_active": true}, fmt.Errorf("no default system domain found"))
+}
+
+// getSystemDomain 按条件获取一个系统域名
+func (s *Store) getSystemDomain(ctx context.Context, filter bson.M, notFound error) (*domain.SystemDomain, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var sysDomain domain.SystemDomain
+	if err := s.findOne(ctx, &sysDomain, filter, notFound); err != nil {
+		return nil, err
+	}
+	if err := s.fillSystemDomainCounts(ctx, &sysDomain); err != nil {
+		return nil, err
+	}
+	return &sysDomain, nil
+}
+
+// ListSystemDomains 获取所有系统域名
+func (s *Store) ListSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
+	return s.listSystemDomains(ctx, bson.M{})
+}
+
+// ListActiveSystemDomains 获取所有已激活的系统域名
+func (s *Store) ListActiveSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
+	return s.listSystemDomains(ctx, bson.M{"is_active": true})
+}
+
+// listSystemDomains 按创建时间倒序列出系统域名
+func (s *Store) listSystemDomains(ctx context.Context, filter bson.M) ([]*domain.SystemDomain, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var sysDomains []*domain.SystemDomain
+	if err := s.find(ctx, &sysDomains, filter, options.Find().SetSort(sortBy("created_at", -1))); err != nil {
+		return nil, err
+	}
+	if err := s.fillSystemDomainCounts(ctx, sysDomains...); err != nil {
+		return nil, err
+	}
+	return sysDomains, nil
+}
+
+// UpdateSystemDomain 更新系统域名
+func (s *Store) UpdateSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.upsert(ctx, bson.M{"_id": sysDomain.ID}, sysDomain)
+}
+
+// DeleteSystemDomain 删除系统域名
+func (s *Store) DeleteSystemDomain(ctx context.Context, domainID string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result, err := s.c(&domain.SystemDomain{}).DeleteOne(ctx, bson.M{"_id": domainID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrSystemDomainNotFound
+	}
+	return nil
+}
+
+// SetDefaultSystemDomain 设置默认系统域名
+//
+// 先设置新的默认域名再取消其他域名：没有事务时中途失败最多短暂出现两个默认域名，而不是没有默认域名。
+func (s *Store) SetDefaultSystemDomain(ctx context.Context, domainID string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.WithTransaction(func(tx *Store) error {
+		ctx := tx.bind(ctx)
+		domains := tx.c(&domain.SystemDomain{})
+		result, err := domains.UpdateOne(ctx, bson.M{"_id": domainID}, bson.M{"$set": bson.M{"is_default": true}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return ErrSystemDomainNotFound
+		}
+		_, err = domains.UpdateMany(ctx, bson.M{"_id": bson.M{"$ne": domainID}, "is_default": true},
+			bson.M{"$set": bson.M{"is_default": false}})
+		return err
+	})
+}
+
+// IncrementSystemDomainMailboxCount 邮箱数读取时实时统计，无需维护
+func (s *Store) IncrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
+	return nil
+}
+
+// DecrementSystemDomainMailboxCount 邮箱数读取时实时统计，无需维护
+func (s *Store) DecrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
+	return nil
+}
+
+// DeleteUnverifiedSystemDomains 删除指定时间前创建且未验证的域名
+func (s *Store) DeleteUnverifiedSystemDomains(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	result, err := s.c(&domain.SystemDomain{}).DeleteMany(ctx, bson.M{
+		"status":     bson.M{"$ne": domain.SystemDomainStatusVerified},
+		"created_at": bson.M{"$lt": before},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
+
+// ========== System Config Repository ==========
+
+// GetSystemConfig 获取系统配置
+func (s *Store) GetSystemConfig(ctx context.Context) (*domain.SystemConfig, error) {
+	// 与 SQL 存储相同，目前返回默认配置
+	return domain.DefaultSystemConfig(), nil
+}
+
+// SaveSystemConfig 保存系统配置
+func (s *Store) SaveSystemConfig(ctx context.Context, config *domain.SystemConfig) error {
+	// 与 SQL 存储相同，目前只返回成功
+	return nil
+}
+
+// ========== User Domain Repository ==========
+
+// SaveUserDomain 保存用户域名
+func (s *Store) SaveUserDomain(ctx context.Context, userDomain *domain.UserDomain) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.upsert(ctx, bson.M{"_id": userDomain.ID}, userDomain)
+}
+
+// GetUserDomain 根据ID获取用户域名
+func (s *Store) GetUserDomain(ctx context.Context, domainID string) (*domain.UserDomain, error) {
+	return s.getUserDomain(ctx, bson.M{"_id": domainID})
+}
+
+// GetUserDomainByDomain 根据域名获取用户域名
+func (s *Store) GetUserDomainByDomain(ctx context.Context, domainName string) (*domain.UserDomain, error) {
+	return s.getUserDomain(ctx, bson.M{"domain": domainName})
+}
+
+// getUserDomain 按条件获取一个用户域名
+func (s *Store) getUserDomain(ctx context.Context, filter bson.M) (*domain.UserDomain, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var userDomain domain.UserDomain
+	if err := s.findOne(ctx, &userDomain, filter, fmt.Errorf("user domain not found")); err != nil {
+		return nil, err
+	}
+	if err := s.fillUserDomainCounts(ctx, &userDomain); err != nil {
+		return nil, err
+	}
+	return &userDomain, nil
+}
+
+// ListUserDomainsByUserID 列出用户的所有域名
+func (s *Store) ListUserDomainsByUserID(ctx context.Context, userID string) ([]*domain.UserDomain, error) {
+	return s.listUserDomains(ctx, bson.M{"user_id": userID})
+}
+
+// ListUserDomainsByOrgID 获取组织的所有域名
+func (s *Store) ListUserDomainsByOrgID(ctx context.Context, orgID string) ([]*domain.UserDomain, error) {
+	return s.listUserDomains(ctx, bson.M{"org_id": orgID})
+}
+
+// ListAllUserDomains 列出所有用户域名
+func (s *Store) ListAllUserDomains(ctx context.Context) ([]*domain.UserDomain, error) {
+	return s.listUserDomains(ctx, bson.M{})
+}
+
+// listUserDomains 按条件列出用户域名
+func (s *Store) listUserDomains(ctx context.Context, filter bson.M) ([]*domain.UserDomain, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var userDomains []*domain.UserDomain
+	if err := s.find(ctx, &userDomains, filter); err != nil {
+		return nil, err
+	}
+	if err := s.fillUserDomainCounts(ctx, userDomains...); err != nil {
+		return nil, err
+	}
+	return userDomains, nil
+}
+
+// UpdateUserDomain 更新用户域名
+func (s *Store) UpdateUserDomain(ctx context.Context, userDomain *domain.UserDomain) error {
+	return s.SaveUserDomain(ctx, userDomain)
+}
+
+// DeleteUserDomain 删除用户域名（域名下还有未过期的邮箱时拒绝）
+func (s *Store) DeleteUserDomain(ctx context.Context, domainID string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.WithTransaction(func(tx *Store) error {
+		ctx := tx.bind(ctx)
+		var userDomain domain.UserDomain
+		if err := tx.findOne(ctx, &userDomain, bson.M{"_id": domainID}, nil); err != nil {
+			return err
+		}
+		if userDomain.ID != "" {
+			count, err := tx.count(ctx, &domain.Mailbox{}, live(bson.M{"domain": userDomain.Domain}, time.Now()))
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("cannot delete domain with active mailboxes")
+			}
+		}
+		if _, err := tx.c(&domain.DomainWhitelistEntry{}).DeleteMany(ctx, bson.M{"domain_id": domainID}); err != nil {
+			return err
+		}
+		_, err := tx.c(&userDomain).DeleteOne(ctx, bson.M{"_id": domainID})
+		return err
+	})
+}
+
+// IncrementMailboxCount 邮箱数读取时实时统计，无需维护
+func (s *Store) IncrementMailboxCount(ctx context.Context, domainName string) error {
+	return nil
+}
+
+// DecrementMailboxCount 邮箱数读取时实时统计，无需维护
+func (s *Store) DecrementMailboxCount(ctx context.Context, domainName string) error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Domain Whitelist Repository ==========
+
+// AddDomainWhitelistEntry 添加白名单条目（依赖 (domain_id, local_part) 唯一索引判断重复）
+func (s *Store) AddDomainWhitelistEntry(ctx context.Context, entry *domain.DomainWhitelistEntry) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	if err := s.insert(ctx, entry); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return storage.ErrWhitelistEntryExists
+		}
+		return err
+	}
+	return nil
+}
+
+// ListDomainWhitelist 列出域名的白名单（按前缀排序）
+func (s *Store) ListDomainWhitelist(ctx context.Context, domainID string) ([]*domain.DomainWhitelistEntry, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var entries []*domain.DomainWhitelistEntry
+	if err := s.find(ctx, &entries, bson.M{"domain_id": domainID}, options.Find().SetSort(sortBy("local_part", 1))); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DeleteDomainWhitelistEntry 删除域名下的白名单条目
+func (s *Store) DeleteDomainWhitelistEntry(ctx context.Context, domainID, id string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.deleteOneContext(ctx, &domain.DomainWhitelistEntry{}, bson.M{"_id": id, "domain_id": domainID}, storage.ErrWhitelistEntryNotFound)
+}
+
+// IsLocalPartWhitelisted 前缀是否在域名白名单中
+func (s *Store) IsLocalPartWhitelisted(ctx context.Context, domainID, localPart string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	count, err := s.count(ctx, &domain.DomainWhitelistEntry{}, bson.M{"domain_id": domainID, "local_part": localPart})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,159 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// mailboxTTLGrace TTL 索引在邮箱过期多久后删除文档
+//
+// 大于每小时一次的清理任务周期，保证清理任务先发送 mailbox.expired 事件（邮箱 Webhook 仍在）再删除，
+// TTL 只兜底清理任务没有运行的情况。
+const mailboxTTLGrace = 2 * time.Hour
+
+// MongoDB 创建索引的错误码：同名索引的选项或键不同
+const (
+	codeIndexOptionsConflict  = 85
+	codeIndexKeySpecsConflict = 86
+)
+
+// ensureIndexes 创建索引（已存在时为空操作）
+//
+// 唯一索引与 SQL 迁移中的唯一约束一一对应，重复写入同样由数据库拒绝；
+// 邮箱的 expires_at 和邮箱下文档的 mailbox_expires_at 为 TTL 索引（过期 mailboxTTLGrace 后删除，
+// 值为 null 的永久邮箱不受影响）。旧版本以其他宽限期创建的 TTL 索引通过 collMod 修改。
+func (s *Store) ensureIndexes(ctx context.Context) error {
+	unique := func(keys ...interface{}) mongo.IndexModel {
+		return mongo.IndexModel{Keys: sortBy(keys...), Options: options.Index().SetUnique(true)}
+	}
+	index := func(keys ...interface{}) mongo.IndexModel {
+		return mongo.IndexModel{Keys: sortBy(keys...)}
+	}
+	ttl := func(field string) mongo.IndexModel {
+		return mongo.IndexModel{
+			Keys:    bson.D{{Key: field, Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(mailboxTTLGrace.Seconds())),
+		}
+	}
+	expiresWithMailbox := ttl(mailboxExpiresField)
+
+	indexes := []struct {
+		model  interface{}
+		models []mongo.IndexModel
+	}{
+		{&domain.Mailbox{}, []mongo.IndexModel{
+			unique("address", 1), unique("token", 1), index("user_id", 1), index("org_id", 1),
+			index("domain", 1), index("is_public", 1), index("last_accessed_at", 1), ttl("expires_at"),
+		}},
+		{&domain.Message{}, []mongo.IndexModel{
+			index("mailbox_id", 1, "seq", -1), index("mailbox_id", 1, "received_at", -1),
+			index("expires_at", 1), expiresWithMailbox,
+		}},
+		{&domain.MessageSequence{}, []mongo.IndexModel{unique("mailbox_id", 1), expiresWithMailbox}},
+		{&domain.MailboxAlias{}, []mongo.IndexModel{unique("address", 1), index("mailbox_id", 1), expiresWithMailbox}},
+		{&domain.Attachment{}, []mongo.IndexModel{index("message_id", 1), index("mailbox_id", 1)}},
+		{&domain.User{}, []mongo.IndexModel{unique("email", 1), index("username", 1)}},
+		{&domain.APIKey{}, []mongo.IndexModel{unique("key_hash", 1), index("user_id", 1)}},
+		{&domain.SystemDomain{}, []mongo.IndexModel{unique("domain", 1)}},
+		{&domain.UserDomain{}, []mongo.IndexModel{unique("domain", 1), index("user_id", 1), index("org_id", 1)}},
+		{&domain.Webhook{}, []mongo.IndexModel{
+			index("user_id", 1), index("org_id", 1), index("owner_type", 1, "owner_id", 1), expiresWithMailbox,
+		}},
+		{&domain.WebhookDelivery{}, []mongo.IndexModel{
+			index("webhook_id", 1, "created_at", -1), index("success", 1, "next_retry", 1),
+			index("mailbox_id", 1), index("dead_letter", 1), expiresWithMailbox,
+		}},
+		{&domain.Tag{}, []mongo.IndexModel{index("user_id", 1, "name", 1), index("org_id", 1)}},
+		{&domain.MessageTag{}, []mongo.IndexModel{
+			unique("message_id", 1, "tag_id", 1), index("tag_id", 1), index("mailbox_id", 1), expiresWithMailbox,
+		}},
+		{&domain.OrgMember{}, []mongo.IndexModel{unique("org_id", 1, "user_id", 1), index("user_id", 1)}},
+		{&domain.OrgInvite{}, []mongo.IndexModel{unique("token", 1), index("org_id", 1)}},
+		{&domain.DistributionList{}, []mongo.IndexModel{unique("address", 1), index("user_id", 1), index("org_id", 1)}},
+		{&domain.DistributionListDelivery{}, []mongo.IndexModel{index("list_id", 1, "created_at", -1)}},
+		{&domain.MessageRedaction{}, []mongo.IndexModel{unique("message_id", 1), index("mailbox_id", 1), expiresWithMailbox}},
+		{&domain.AbuseReport{}, []mongo.IndexModel{index("status", 1, "created_at", -1), index("mailbox_id", 1)}},
+		{&domain.MessageShare{}, []mongo.IndexModel{index("mailbox_id", 1), expiresWithMailbox}},
+		{&domain.SentMessage{}, []mongo.IndexModel{
+			index("mailbox_id", 1, "sent_at", -1), index("user_id", 1, "sent_at", -1), expiresWithMailbox,
+		}},
+		{&domain.DomainWhitelistEntry{}, []mongo.IndexModel{unique("domain_id", 1, "local_part", 1)}},
+		{&domain.ReservedPrefix{}, []mongo.IndexModel{unique("domain", 1, "local_part", 1), index("user_id", 1)}},
+		{&domain.OAuthIdentity{}, []mongo.IndexModel{unique("provider", 1, "subject", 1), index("user_id", 1)}},
+		{&domain.UserSession{}, []mongo.IndexModel{index("user_id", 1), index("expires_at", 1)}},
+		{&domain.AuditLog{}, []mongo.IndexModel{
+			index("created_at", -1), index("actor_id", 1), index("action", 1), index("target_type", 1, "target_id", 1),
+		}},
+		{&domain.OutboxEvent{}, []mongo.IndexModel{
+			index("processed_at", 1, "next_attempt_at", 1), index("claim_token", 1),
+		}},
+		{&domain.MailboxForward{}, []mongo.IndexModel{index("mailbox_id", 1), expiresWithMailbox}},
+		{&domain.ForwardDelivery{}, []mongo.IndexModel{
+			index("forward_id", 1), index("mailbox_id", 1), index("success", 1, "next_retry", 1), expiresWithMailbox,
+		}},
+		{&domain.SearchDocument{}, []mongo.IndexModel{
+			unique("message_id", 1), index("mailbox_id", 1, "received_at", -1), index(termSetField, 1), expiresWithMailbox,
+		}},
+		{&domain.MaintenanceJob{}, []mongo.IndexModel{{
+			// SQL 的唯一索引允许多个 NULL，这里只约束字符串值（排队或运行中的任务）
+			Keys: bson.D{{Key: "active_type", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"active_type": bson.M{"$type": "string"}}),
+		}}},
+		{&domain.AnalyticsBucket{}, []mongo.IndexModel{unique("metric", 1, "dimension", 1, "bucket", 1), index("bucket", 1)}},
+		{&domain.MailFlowCounter{}, []mongo.IndexModel{unique("kind", 1, "dimension", 1, "bucket", 1), index("bucket", 1)}},
+	}
+
+	for _, entry := range indexes {
+		coll := s.c(entry.model)
+		_, err := coll.Indexes().CreateMany(ctx, entry.models)
+		if isIndexConflict(err) {
+			if err = updateTTL(ctx, coll, entry.models); err == nil {
+				_, err = coll.Indexes().CreateMany(ctx, entry.models)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", collectionOf(entry.model), err)
+		}
+	}
+	return nil
+}
+
+// isIndexConflict 已有同名索引但选项不同（如 TTL 宽限期变化）
+func isIndexConflict(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) &&
+		(cmdErr.Code == codeIndexOptionsConflict || cmdErr.Code == codeIndexKeySpecsConflict)
+}
+
+// updateTTL 将集合中已有 TTL 索引的 expireAfterSeconds 改为 models 中的值（索引不存在时跳过）
+func updateTTL(ctx context.Context, coll *mongo.Collection, models []mongo.IndexModel) error {
+	for _, model := range models {
+		if model.Options == nil || model.Options.ExpireAfterSeconds == nil {
+			continue
+		}
+		err := coll.Database().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: coll.Name()},
+			{Key: "index", Value: bson.D{
+				{Key: "keyPattern", Value: model.Keys},
+				{Key: "expireAfterSeconds", Value: *model.Options.ExpireAfterSeconds},
+			}},
+		}).Err()
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,437 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Mailbox Repository ==========
+
+// CreateMailbox 创建新邮箱（依赖 address、token 唯一索引判断冲突），地址已被邮箱或别名占用时返回 storage.ErrAddressTaken
+//
+// 邮箱和别名不在同一个集合，插入后再确认没有同时创建的同地址别名，有则撤销本次插入（与 SQL 存储相同）。
+func (s *Store) CreateMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	if err := s.insert(ctx, mailbox); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return storage.ErrAddressTaken
+		}
+		return err
+	}
+
+	aliases, err := s.count(ctx, &domain.MailboxAlias{}, bson.M{"address": mailbox.Address})
+	if err != nil {
+		return err
+	}
+	if aliases > 0 {
+		if _, err := s.c(mailbox).DeleteOne(ctx, bson.M{"_id": mailbox.ID}); err != nil {
+			return err
+		}
+		return storage.ErrAddressTaken
+	}
+	return nil
+}
+
+// SaveMailbox 保存邮箱信息，过期时间变化时同步到邮箱下的文档
+func (s *Store) SaveMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	set, err := s.fields(mailbox)
+	if err != nil {
+		return err
+	}
+	var previous domain.Mailbox
+	err = s.c(mailbox).FindOneAndUpdate(ctx, bson.M{"_id": mailbox.ID}, bson.M{"$set": set},
+		options.FindOneAndUpdate().
+			SetUpsert(true).
+			SetReturnDocument(options.Before).
+			SetProjection(bson.M{"expires_at": 1})).Decode(&previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil // 新建的邮箱下还没有文档
+	}
+	if err != nil {
+		return err
+	}
+	if sameTime(previous.ExpiresAt, mailbox.ExpiresAt) {
+		return nil
+	}
+	return s.syncMailboxExpiry(ctx, mailbox.ID, mailbox.ExpiresAt)
+}
+
+// sameTime 两个可空时间是否相同（按毫秒比较，与 BSON 日期精度一致）
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Truncate(time.Millisecond).Equal(b.Truncate(time.Millisecond))
+}
+
+// GetMailbox 根据 ID 获取邮箱
+func (s *Store) GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var mailbox domain.Mailbox
+	if err := s.findOne(ctx, &mailbox, live(bson.M{"_id": id}, time.Now()), ErrMailboxNotFound); err != nil {
+		return nil, err
+	}
+	return &mailbox, nil
+}
+
+// GetMailboxByAddress 根据完整地址获取邮箱
+func (s *Store) GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var mailbox domain.Mailbox
+	if err := s.findOne(ctx, &mailbox, live(bson.M{"address": address}, time.Now()), ErrMailboxNotFound); err != nil {
+		return nil, err
+	}
+	return &mailbox, nil
+}
+
+// ListMailboxes 返回全部邮箱的快照
+func (s *Store) ListMailboxes(ctx context.Context) []domain.Mailbox {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var mailboxes []domain.Mailbox
+	s.find(ctx, &mailboxes, live(bson.M{}, time.Now()))
+	return mailboxes
+}
+
+// ListMailboxesByUserID 返回指定用户的全部邮箱
+func (s *Store) ListMailboxesByUserID(ctx context.Context, userID string) []domain.Mailbox {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var mailboxes []domain.Mailbox
+	s.find(ctx, &mailboxes, live(bson.M{"user_id": userID}, time.Now()))
+	return mailboxes
+}
+
+// GetMailboxesByAddresses 批量按地址查询未过期的邮箱，不存在的地址直接忽略
+func (s *Store) GetMailboxesByAddresses(ctx context.Context, addresses []string) ([]domain.Mailbox, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	if len(addresses) == 0 {
+		return []domain.Mailbox{}, nil
+	}
+	var mailboxes []domain.Mailbox
+	if err := s.find(ctx, &mailboxes, live(bson.M{"address": bson.M{"$in": addresses}}, time.Now())); err != nil {
+		return nil, err
+	}
+	return mailboxes, nil
+}
+
+// ListMailboxesByOrgID 根据组织ID获取邮箱列表
+func (s *Store) ListMailboxesByOrgID(ctx context.Context, orgID string) []domain.Mailbox {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var mailboxes []domain.Mailbox
+	s.find(ctx, &mailboxes, live(bson.M{"org_id": orgID}, time.Now()))
+	return mailboxes
+}
+
+// DeleteMailbox 删除指定邮箱
+func (s *Store) DeleteMailbox(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return s.deleteMailboxes(ctx, []string{id})
+}
+
+// deleteMailboxes 删除邮箱及其邮件、标签关联、别名等数据（与 SQL 存储的级联范围相同）
+//
+// 邮箱最后删除：中途失败时邮箱仍在，重试会继续清理剩余的文档。
+func (s *Store) deleteMailboxes(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	inMailboxes := bson.M{"mailbox_id": bson.M{"$in": ids}}
+
+	for _, model := range []interface{}{
+		&domain.MessageTag{},
+		&domain.MessageRedaction{},
+		&domain.MessageShare{},
+		&domain.SentMessage{},
+		&domain.MailboxForward{},
+		&domain.ForwardDelivery{},
+		&domain.SearchDocument{},
+		&domain.Message{},
+		&domain.MailboxAlias{},
+		&domain.MessageSequence{},
+	} {
+		if _, err := s.c(model).DeleteMany(ctx, inMailboxes); err != nil {
+			return err
+		}
+	}
+
+	// 删除邮箱 Webhook 及其投递记录
+	mailboxWebhooks := bson.M{"owner_type": domain.WebhookOwnerMailbox, "owner_id": bson.M{"$in": ids}}
+	webhookIDs, err := s.c(&domain.Webhook{}).Distinct(ctx, "_id", mailboxWebhooks)
+	if err != nil {
+		return err
+	}
+	if len(webhookIDs) > 0 {
+		if _, err := s.c(&domain.WebhookDelivery{}).DeleteMany(ctx, bson.M{"webhook_id": bson.M{"$in": webhookIDs}}); err != nil {
+			return err
+		}
+	}
+	if _, err := s.c(&domain.Webhook{}).DeleteMany(ctx, mailboxWebhooks); err != nil {
+		return err
+	}
+
+	// 删除邮箱
+	_, err = s.c(&domain.Mailbox{}).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
+
+// ListExpiredMailboxes 列出在指定时间已过期（TTL 尚未删除）的邮箱
+func (s *Store) ListExpiredMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var mailboxes []domain.Mailbox
+	if err := s.find(ctx, &mailboxes, bson.M{"expires_at": bson.M{"$ne": nil, "$lte": now}}); err != nil {
+		return nil, err
+	}
+	return mailboxes, nil
+}
+
+// TouchMailbox 记录访问时间，并恢复闲置时被缩短的有效期
+func (s *Store) TouchMailbox(ctx context.Context, mailboxID string, at time.Time) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	// 聚合管道更新：同一阶段内的表达式读取的都是更新前的值
+	update := bson.A{bson.M{"$set": bson.M{
+		"expires_at":               bson.M{"$cond": bson.A{"$idle_shortened", "$idle_original_expires_at", "$expires_at"}},
+		"last_accessed_at":         at,
+		"idle_since":               nil,
+		"idle_shortened":           false,
+		"idle_original_expires_at": nil,
+	}}}
+	var previous domain.Mailbox
+	err := s.c(&previous).FindOneAndUpdate(ctx, bson.M{"_id": mailboxID}, update,
+		options.FindOneAndUpdate().SetProjection(bson.M{"idle_shortened": 1, "idle_original_expires_at": 1})).Decode(&previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrMailboxNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !previous.IdleShortened {
+		return nil
+	}
+	return s.syncMailboxExpiry(ctx, mailboxID, previous.IdleOriginalExpiresAt)
+}
+
+// ListIdleMailboxes 列出 before 之后未被访问、尚未标记闲置且未过期的用户邮箱
+func (s *Store) ListIdleMailboxes(ctx context.Context, before, now time.Time) ([]domain.Mailbox, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	filter := bson.M{
+		"user_id":    bson.M{"$ne": nil},
+		"idle_since": nil,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"last_accessed_at": bson.M{"$lt": before}},
+				bson.M{"last_accessed_at": nil, "created_at": bson.M{"$lt": before}},
+			}},
+			bson.M{"$or": bson.A{bson.M{"expires_at": nil}, bson.M{"expires_at": bson.M{"$gt": now}}}},
+		},
+	}
+	var mailboxes []domain.Mailbox
+	if err := s.find(ctx, &mailboxes, filter); err != nil {
+		return nil, err
+	}
+	return mailboxes, nil
+}
+
+// ListPublicMailboxes 列出未过期的公开收件箱
+func (s *Store) ListPublicMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var mailboxes []domain.Mailbox
+	err := s.find(ctx, &mailboxes, live(bson.M{"is_public": true}, now), options.Find().SetSort(sortBy("address", 1)))
+	if err != nil {
+		return nil, err
+	}
+	return mailboxes, nil
+}
+
+// MarkMailboxIdle 标记邮箱闲置，shortenTo 非空时缩短有效期
+func (s *Store) MarkMailboxIdle(ctx context.Context, mailboxID string, at time.Time, shortenTo *time.Time) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var update interface{} = bson.M{"$set": bson.M{"idle_since": at}}
+	if shortenTo != nil {
+		// 先保存原始过期时间再覆盖（已缩短过的保留第一次的原值）
+		update = bson.A{bson.M{"$set": bson.M{
+			"idle_original_expires_at": bson.M{"$cond": bson.A{"$idle_shortened", "$idle_original_expires_at", "$expires_at"}},
+			"expires_at":               *shortenTo,
+			"idle_shortened":           true,
+			"idle_since":               at,
+		}}}
+	}
+	result, err := s.c(&domain.Mailbox{}).UpdateOne(ctx, bson.M{"_id": mailboxID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrMailboxNotFound
+	}
+	if shortenTo == nil {
+		return nil
+	}
+	return s.syncMailboxExpiry(ctx, mailboxID, shortenTo)
+}
+
+// ExtendMailbox 设置未过期邮箱新的过期时间并清除闲置状态
+func (s *Store) ExtendMailbox(ctx context.Context, mailboxID string, expiresAt time.Time) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result, err := s.c(&domain.Mailbox{}).UpdateOne(ctx, live(bson.M{"_id": mailboxID}, time.Now()), bson.M{"$set": bson.M{
+		"expires_at":               expiresAt,
+		"idle_since":               nil,
+		"idle_shortened":           false,
+		"idle_original_expires_at": nil,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrMailboxNotFound
+	}
+	return s.syncMailboxExpiry(ctx, mailboxID, &expiresAt)
+}
+
+// DeleteExpiredMailboxes 删除所有过期的邮箱，返回删除数量
+//
+// 过期邮箱先由清理任务经 MailboxService.DeleteExpired 发送事件后删除，TTL 索引在宽限期后兜底。
+func (s *Store) DeleteExpiredMailboxes(ctx context.Context) (int, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	ids, err := s.c(&domain.Mailbox{}).Distinct(ctx, "_id", bson.M{"expires_at": bson.M{"$ne": nil, "$lte": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+	if err := s.deleteMailboxes(ctx, stringIDs(ids)); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// stringIDs Distinct 返回的 ID 转为字符串
+func stringIDs(values []interface{}) []string {
+	ids := make([]string, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ========== Alias Repository ==========
+
+// SaveAlias 保存邮箱别名（别名随所属邮箱一起过期）
+func (s *Store) SaveAlias(ctx context.Context, alias *domain.MailboxAlias) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	// 检查邮箱是否存在
+	var mailbox domain.Mailbox
+	if err := s.findOne(ctx, &mailbox, bson.M{"_id": alias.MailboxID}, ErrMailboxNotFound); err != nil {
+		return err
+	}
+
+	// 检查别名地址是否已被邮箱使用
+	mailboxes, err := s.count(ctx, &domain.Mailbox{}, bson.M{"address": alias.Address})
+	if err != nil {
+		return err
+	}
+	if mailboxes > 0 {
+		return storage.ErrAddressTaken
+	}
+
+	// 检查地址是否被其他别名使用
+	filter := bson.M{"address": alias.Address}
+	if alias.ID != "" {
+		filter["_id"] = bson.M{"$ne": alias.ID}
+	}
+	aliases, err := s.count(ctx, alias, filter)
+	if err != nil {
+		return err
+	}
+	if aliases > 0 {
+		return ErrAliasExists
+	}
+
+	if err := s.upsert(ctx, bson.M{"_id": alias.ID}, alias, expiresWith(mailbox.ExpiresAt)); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrAliasExists
+		}
+		return err
+	}
+
+	// 写入后再确认没有同时创建的同地址邮箱（见 CreateMailbox）
+	mailboxes, err = s.count(ctx, &domain.Mailbox{}, bson.M{"address": alias.Address})
+	if err != nil {
+		return err
+	}
+	if mailboxes > 0 {
+		if _, err := s.c(alias).DeleteOne(ctx, bson.M{"_id": alias.ID}); err != nil {
+			return err
+		}
+		return storage.ErrAddressTaken
+	}
+	return nil
+}
+
+// GetAlias 根据ID获取别名
+func (s *Store) GetAlias(ctx context.Context, aliasID string) (*domain.MailboxAlias, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var alias domain.MailboxAlias
+	if err := s.findOne(ctx, &alias, bson.M{"_id": aliasID}, ErrAliasNotFound); err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// GetAliasByAddress 根据地址获取别名
+func (s *Store) GetAliasByAddress(ctx context.Context, address string) (*domain.MailboxAlias, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var alias domain.MailboxAlias
+	if err := s.findOne(ctx, &alias, bson.M{"address": address}, ErrAliasNotFound); err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// ListAliasesByMailboxID 列出指定邮箱的所有别名
+func (s *Store) ListAliasesByMailboxID(ctx context.Context, mailboxID string) ([]*domain.MailboxAlias, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var aliases []*domain.MailboxAlias
+	err := s.find(ctx, &aliases, bson.M{"mailbox_id": mailboxID})
+	return aliases, err
+}
+
+// DeleteAlias 删除别名
+func (s *Store) DeleteAlias(ctx context.Context, aliasID string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	_, err := s.c(&domain.MailboxAlias{}).DeleteOne(ctx, bson.M{"_id": aliasID})
+	return err
+}
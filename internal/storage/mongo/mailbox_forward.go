@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Forward Repository ==========
+
+// SaveMailboxForward 保存转发地址（新建或更新，随邮箱一起过期）
+func (s *Store) SaveMailboxForward(ctx context.Context, forward *domain.MailboxForward) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	expiresAt, err := s.mailboxExpiry(ctx, forward.MailboxID)
+	if err != nil {
+		return err
+	}
+	return s.upsert(ctx, bson.M{"_id": forward.ID}, forward, expiresWith(expiresAt))
+}
+
+// GetMailboxForward 获取邮箱下的转发地址
+func (s *Store) GetMailboxForward(ctx context.Context, mailboxID, id string) (*domain.MailboxForward, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var forward domain.MailboxForward
+	if err := s.findOne(ctx, &forward, bson.M{"_id": id, "mailbox_id": mailboxID}, storage.ErrForwardNotFound); err != nil {
+		return nil, err
+	}
+	return &forward, nil
+}
+
+// ListMailboxForwards 列出邮箱的转发地址（按创建时间排序）
+func (s *Store) ListMailboxForwards(ctx context.Context, mailboxID string) ([]*domain.MailboxForward, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var forwards []*domain.MailboxForward
+	err := s.find(ctx, &forwards, bson.M{"mailbox_id": mailboxID}, options.Find().SetSort(sortBy("created_at", 1, "_id", 1)))
+	if err != nil {
+		return nil, err
+	}
+	return forwards, nil
+}
+
+// DeleteMailboxForward 删除邮箱下的转发地址及其投递记录
+func (s *Store) DeleteMailboxForward(ctx context.Context, mailboxID, id string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.WithTransaction(func(tx *Store) error {
+		ctx := tx.bind(ctx)
+		err := tx.deleteOneContext(ctx, &domain.MailboxForward{}, bson.M{"_id": id, "mailbox_id": mailboxID}, storage.ErrForwardNotFound)
+		if err != nil {
+			return err
+		}
+		_, err = tx.c(&domain.ForwardDelivery{}).DeleteMany(ctx, bson.M{"forward_id": id})
+		return err
+	})
+}
+
+// SaveForwardDelivery 保存投递记录（新建或更新，随邮箱一起过期）
+func (s *Store) SaveForwardDelivery(ctx context.Context, delivery *domain.ForwardDelivery) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	expiresAt, err := s.mailboxExpiry(ctx, delivery.MailboxID)
+	if err != nil {
+		return err
+	}
+	return s.upsert(ctx, bson.M{"_id": delivery.ID}, delivery, expiresWith(expiresAt))
+}
+
+// ListPendingForwardDeliveries 列出到期待投递的记录（按 NextRetry 排序）
+func (s *Store) ListPendingForwardDeliveries(ctx context.Context, now time.Time, limit int) ([]*domain.ForwardDelivery, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	opts := options.Find().SetSort(sortBy("next_retry", 1))
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	var deliveries []*domain.ForwardDelivery
+	filter := bson.M{"success": false, "next_retry": bson.M{"$ne": nil, "$lte": now}}
+	if err := s.find(ctx, &deliveries, filter, opts); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,101 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"tempmail/backend/internal/domain"
+)
+
+// mailboxSummaryRow 邮箱摘要聚合结果（邮箱没有邮件时 stats 和 latest 为空）
+type mailboxSummaryRow struct {
+	domain.Mailbox `bson:",inline"`
+	Stats          []struct {
+		Total  int   `bson:"total"`
+		Unread int   `bson:"unread"`
+		Bytes  int64 `bson:"bytes"`
+	} `bson:"summary_stats"`
+	Latest []domain.Message `bson:"summary_latest"`
+}
+
+// ListMailboxSummariesByUserID 返回指定用户的邮箱摘要
+func (s *Store) ListMailboxSummariesByUserID(ctx context.Context, userID string) ([]domain.MailboxSummary, error) {
+	return s.listMailboxSummaries(ctx, "user_id", userID)
+}
+
+// ListMailboxSummariesByOrgID 返回指定组织的邮箱摘要
+func (s *Store) ListMailboxSummariesByOrgID(ctx context.Context, orgID string) ([]domain.MailboxSummary, error) {
+	return s.listMailboxSummaries(ctx, "org_id", orgID)
+}
+
+// listMailboxSummaries 用一次聚合取出邮箱、按邮件实时统计的数量和最近一封邮件：
+// 两个 $lookup 子管道分别按邮箱分组统计和按 (mailbox_id, seq) 索引取第一条。
+// 隔离区邮件不计入数量和预览，但计入占用字节。
+func (s *Store) listMailboxSummaries(ctx context.Context, field, value string) ([]domain.MailboxSummary, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	messages := collectionOf(&domain.Message{})
+	inMailbox := bson.M{"$expr": bson.M{"$eq": bson.A{"$mailbox_id", "$$mailbox"}}}
+	cursor, err := s.c(&domain.Mailbox{}).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: live(bson.M{field: value}, time.Now())}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": messages,
+			"let":  bson.M{"mailbox": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": inMailbox},
+				bson.M{"$group": bson.M{
+					"_id":    nil,
+					"total":  bson.M{"$sum": bson.M{"$cond": bson.A{"$quarantined", 0, 1}}},
+					"unread": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$or": bson.A{"$quarantined", "$is_read"}}, 0, 1}}},
+					"bytes":  bson.M{"$sum": "$size"},
+				}},
+			},
+			"as": "summary_stats",
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": messages,
+			"let":  bson.M{"mailbox": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": inMailbox},
+				bson.M{"$match": bson.M{"quarantined": false}},
+				bson.M{"$sort": sortBy("seq", -1, "received_at", -1, "_id", -1)},
+				bson.M{"$limit": 1},
+				bson.M{"$project": bson.M{"subject": 1, "from": 1, "received_at": 1}},
+			},
+			"as": "summary_latest",
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mailbox summaries: %w", err)
+	}
+	var rows []mailboxSummaryRow
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to list mailbox summaries: %w", err)
+	}
+
+	summaries := make([]domain.MailboxSummary, 0, len(rows))
+	for _, row := range rows {
+		summary := domain.MailboxSummary{Mailbox: row.Mailbox}
+		if len(row.Stats) > 0 {
+			summary.TotalCount = row.Stats[0].Total
+			summary.Unread = row.Stats[0].Unread
+			summary.StorageBytes = row.Stats[0].Bytes
+		}
+		if len(row.Latest) > 0 {
+			latest := row.Latest[0]
+			summary.LastMessage = &domain.MessagePreview{
+				ID:         latest.ID,
+				Subject:    latest.Subject,
+				From:       latest.From,
+				ReceivedAt: latest.ReceivedAt,
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
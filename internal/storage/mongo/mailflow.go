@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Mail Flow Repository ==========
+
+// MergeMailFlowCounters 累加收信流量计数（$inc upsert，多个实例同时写入同一分钟不会丢失计数）
+func (s *Store) MergeMailFlowCounters(ctx context.Context, counters []domain.MailFlowCounter) error {
+	if len(counters) == 0 {
+		return nil
+	}
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, 0, len(counters))
+	for _, counter := range counters {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"kind": counter.Kind, "dimension": counter.Dimension, "bucket": counter.Bucket.UTC()}).
+			SetUpdate(bson.M{"$inc": bson.M{"count": counter.Count}}).
+			SetUpsert(true))
+	}
+	_, err := s.c(&domain.MailFlowCounter{}).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// ListMailFlowCounters 列出 [since, until) 内的收信流量计数
+func (s *Store) ListMailFlowCounters(ctx context.Context, since, until time.Time) ([]domain.MailFlowCounter, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var counters []domain.MailFlowCounter
+	err := s.find(ctx, &counters,
+		bson.M{"bucket": bson.M{"$gte": since.UTC(), "$lt": until.UTC()}},
+		options.Find().SetSort(sortBy("bucket", 1, "kind", 1, "dimension", 1)))
+	for i := range counters {
+		counters[i].Bucket = counters[i].Bucket.UTC()
+	}
+	return counters, err
+}
+
+// DeleteMailFlowCountersBefore 删除早于 before 的收信流量计数
+func (s *Store) DeleteMailFlowCountersBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	result, err := s.c(&domain.MailFlowCounter{}).DeleteMany(ctx, bson.M{"bucket": bson.M{"$lt": before.UTC()}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
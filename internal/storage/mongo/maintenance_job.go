@@ -0,0 +1,169 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Maintenance Job Repository ==========
+
+// CreateMaintenanceJob 创建维护任务
+//
+// 同类型已有排队或运行中的任务时拒绝，由 active_type 的部分唯一索引保证（并发创建只有一个成功）。
+func (s *Store) CreateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	job.SyncActiveType()
+	err := s.insert(ctx, job)
+	if mongo.IsDuplicateKeyError(err) && job.ActiveType != nil {
+		return storage.ErrMaintenanceJobActive
+	}
+	return err
+}
+
+// GetMaintenanceJob 获取维护任务
+func (s *Store) GetMaintenanceJob(ctx context.Context, id string) (*domain.MaintenanceJob, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var job domain.MaintenanceJob
+	if err := s.findOne(ctx, &job, bson.M{"_id": id}, storage.ErrMaintenanceJobNotFound); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListMaintenanceJobs 列出维护任务（按创建时间倒序）
+func (s *Store) ListMaintenanceJobs(ctx context.Context) ([]*domain.MaintenanceJob, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var jobs []*domain.MaintenanceJob
+	if err := s.find(ctx, &jobs, bson.M{}, options.Find().SetSort(sortBy("created_at", -1, "_id", -1))); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// UpdateMaintenanceJob 保存维护任务状态和进度，任务结束后清空 active_type 释放占用
+func (s *Store) UpdateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	job.SyncActiveType()
+	set, err := s.fields(job)
+	if err != nil {
+		return err
+	}
+	update := make(bson.D, 0, len(set))
+	for _, e := range set {
+		if e.Key != "created_at" {
+			update = append(update, e)
+		}
+	}
+	result, err := s.c(job).UpdateOne(ctx, bson.M{"_id": job.ID}, bson.M{"$set": update})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return storage.ErrMaintenanceJobNotFound
+	}
+	return nil
+}
+
+// ========== Maintenance Scan ==========
+
+// CountMessages 统计全部邮件数
+func (s *Store) CountMessages(ctx context.Context) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return s.count(ctx, &domain.Message{}, bson.M{})
+}
+
+// ListMessagesAfter 按 ID 升序列出 ID 大于 afterID 的邮件（只取元数据，不加载附件）
+func (s *Store) ListMessagesAfter(ctx context.Context, afterID string, limit int) ([]domain.Message, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var messages []domain.Message
+	err := s.find(ctx, &messages, bson.M{"_id": bson.M{"$gt": afterID}},
+		options.Find().SetSort(sortBy("_id", 1)).SetLimit(int64(limit)))
+	return messages, err
+}
+
+// SetMessageDerivedText 标记邮件已有由 HTML 生成的纯文本（正文本身不存数据库）
+func (s *Store) SetMessageDerivedText(ctx context.Context, mailboxID, messageID, text string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result, err := s.c(&domain.Message{}).UpdateOne(ctx, bson.M{"_id": messageID, "mailbox_id": mailboxID},
+		bson.M{"$set": bson.M{"has_text": true, "text_derived_from_html": true}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// CountMailboxes 统计全部邮箱数
+func (s *Store) CountMailboxes(ctx context.Context) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return s.count(ctx, &domain.Mailbox{}, bson.M{})
+}
+
+// ListMailboxIDsAfter 按 ID 升序列出 ID 大于 afterID 的邮箱 ID
+func (s *Store) ListMailboxIDsAfter(ctx context.Context, afterID string, limit int) ([]string, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var mailboxes []domain.Mailbox
+	err := s.find(ctx, &mailboxes, bson.M{"_id": bson.M{"$gt": afterID}}, options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetSort(sortBy("_id", 1)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(mailboxes))
+	for _, mailbox := range mailboxes {
+		ids = append(ids, mailbox.ID)
+	}
+	return ids, nil
+}
+
+// RecountMailbox 按邮件重新统计邮箱的总数和未读数
+//
+// 统计后以旧值为条件写回：期间有新邮件改变了计数时不覆盖（下次扫描再修正）；统计没有变化时不写。
+func (s *Store) RecountMailbox(ctx context.Context, mailboxID string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var mailbox domain.Mailbox
+	err := s.findOne(ctx, &mailbox, bson.M{"_id": mailboxID}, nil,
+		options.FindOne().SetProjection(bson.M{"total_count": 1, "unread": 1}))
+	if err != nil || mailbox.ID == "" {
+		return false, err
+	}
+	total, err := s.count(ctx, &domain.Message{}, bson.M{"mailbox_id": mailboxID})
+	if err != nil {
+		return false, err
+	}
+	unread, err := s.count(ctx, &domain.Message{}, bson.M{"mailbox_id": mailboxID, "is_read": false})
+	if err != nil {
+		return false, err
+	}
+	if int64(mailbox.TotalCount) == total && int64(mailbox.Unread) == unread {
+		return false, nil
+	}
+
+	result, err := s.c(&mailbox).UpdateOne(ctx,
+		bson.M{"_id": mailboxID, "total_count": mailbox.TotalCount, "unread": mailbox.Unread},
+		bson.M{"$set": bson.M{"total_count": total, "unread": unread}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
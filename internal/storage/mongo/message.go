@@ -0,0 +1,420 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Message Repository ==========
+
+// SaveMessage 保存邮件信息，分配邮箱内序号并更新邮箱统计
+//
+// 序号计数用 $inc 原子递增，并发写入同一邮箱时不会分配重复序号；邮件写入失败时撤销统计。
+func (s *Store) SaveMessage(ctx context.Context, message *domain.Message) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	unread := 0
+	if !message.IsRead {
+		unread = 1
+	}
+	expiresAt, err := s.incrementMailboxCounts(ctx, message.MailboxID, 1, unread)
+	if err != nil {
+		return err
+	}
+
+	err = s.saveMessage(ctx, message, expiresAt)
+	if err != nil {
+		// 尽力撤销统计，返回原始错误
+		_, _ = s.incrementMailboxCounts(ctx, message.MailboxID, -1, -unread)
+	}
+	return err
+}
+
+// saveMessage 分配（或推进）序号后写入邮件
+func (s *Store) saveMessage(ctx context.Context, message *domain.Message, expiresAt *time.Time) error {
+	// 已带序号（快照迁移、重复保存）时保留，并推进计数
+	if message.Seq > 0 {
+		if err := s.advanceMessageSeq(ctx, message.MailboxID, message.Seq, expiresAt); err != nil {
+			return err
+		}
+	} else {
+		last, err := s.reserveMessageSeqs(ctx, message.MailboxID, 1, expiresAt)
+		if err != nil {
+			return err
+		}
+		message.Seq = last
+	}
+	return s.upsert(ctx, bson.M{"_id": message.ID}, message, expiresWith(expiresAt))
+}
+
+// incrementMailboxCounts 累加邮箱的邮件总数和未读数，返回邮箱的过期时间（写入邮件时冗余保存）
+func (s *Store) incrementMailboxCounts(ctx context.Context, mailboxID string, total, unread int) (*time.Time, error) {
+	var mailbox domain.Mailbox
+	err := s.c(&mailbox).FindOneAndUpdate(ctx, bson.M{"_id": mailboxID},
+		bson.M{"$inc": bson.M{"total_count": total, "unread": unread}},
+		options.FindOneAndUpdate().SetProjection(bson.M{"expires_at": 1})).Decode(&mailbox)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrMailboxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mailbox.ExpiresAt, nil
+}
+
+// reserveMessageSeqs 为邮箱预留 count 个连续序号，返回其中最大的一个
+func (s *Store) reserveMessageSeqs(ctx context.Context, mailboxID string, count int, expiresAt *time.Time) (int64, error) {
+	var sequence domain.MessageSequence
+	err := s.c(&sequence).FindOneAndUpdate(ctx, bson.M{"mailbox_id": mailboxID},
+		bson.M{"$inc": bson.M{"last_seq": int64(count)}, "$set": bson.D{expiresWith(expiresAt)}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&sequence)
+	if err != nil {
+		return 0, err
+	}
+	return sequence.LastSeq, nil
+}
+
+// advanceMessageSeq 保留已有序号时把计数推进到不小于该序号
+func (s *Store) advanceMessageSeq(ctx context.Context, mailboxID string, seq int64, expiresAt *time.Time) error {
+	_, err := s.c(&domain.MessageSequence{}).UpdateOne(ctx, bson.M{"mailbox_id": mailboxID},
+		bson.M{"$max": bson.M{"last_seq": seq}, "$set": bson.D{expiresWith(expiresAt)}},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+// SaveMessages 批量插入邮件，并按邮箱累加统计
+func (s *Store) SaveMessages(ctx context.Context, messages []*domain.Message) error {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	if len(messages) == 0 {
+		return nil
+	}
+	return s.WithTransaction(func(tx *Store) error {
+		return tx.saveMessages(tx.bind(ctx), messages)
+	})
+}
+
+// saveMessages 批量写入邮件并累加统计（调用方负责事务，ctx 已绑定事务会话）
+func (s *Store) saveMessages(ctx context.Context, messages []*domain.Message) error {
+	type counts struct {
+		total, unread int
+		next          int64 // 下一个待分配的序号
+		expiresAt     *time.Time
+	}
+	perMailbox := make(map[string]*counts)
+	order := make([]string, 0)
+	for _, message := range messages {
+		c, ok := perMailbox[message.MailboxID]
+		if !ok {
+			c = &counts{}
+			perMailbox[message.MailboxID] = c
+			order = append(order, message.MailboxID)
+		}
+		c.total++
+		if !message.IsRead {
+			c.unread++
+		}
+	}
+
+	// 先更新邮箱统计并预留序号，再按输入顺序分配
+	for _, mailboxID := range order {
+		c := perMailbox[mailboxID]
+		expiresAt, err := s.incrementMailboxCounts(ctx, mailboxID, c.total, c.unread)
+		if err != nil {
+			return err
+		}
+		last, err := s.reserveMessageSeqs(ctx, mailboxID, c.total, expiresAt)
+		if err != nil {
+			return err
+		}
+		c.next = last - int64(c.total) + 1
+		c.expiresAt = expiresAt
+	}
+
+	docs := make([]interface{}, 0, len(messages))
+	for _, message := range messages {
+		c := perMailbox[message.MailboxID]
+		message.Seq = c.next
+		c.next++
+		doc, err := s.encode(message)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, append(doc, expiresWith(c.expiresAt)))
+	}
+	_, err := s.c(&domain.Message{}).InsertMany(ctx, docs)
+	return err
+}
+
+// ListMessages 返回某个邮箱下的全部邮件
+func (s *Store) ListMessages(ctx context.Context, mailboxID string) ([]domain.Message, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var messages []domain.Message
+	err := s.find(ctx, &messages, bson.M{"mailbox_id": mailboxID}, options.Find().SetSort(sortBy("seq", -1, "created_at", -1)))
+	return messages, err
+}
+
+// ListMessagesPage 按条件过滤、排序并分页列出邮件（排序与 domain.SortMessages 一致）
+func (s *Store) ListMessagesPage(ctx context.Context, q domain.MessageListQuery) (*domain.MessagePage, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	filter := bson.M{"mailbox_id": q.MailboxID, "quarantined": q.Quarantined}
+	if q.UnreadOnly {
+		filter["is_read"] = false
+	}
+	if q.IsSpam != nil {
+		filter["is_spam"] = *q.IsSpam
+	}
+	if err := matchHeaders(filter, q.Headers); err != nil {
+		return nil, err
+	}
+
+	total, err := s.count(ctx, &domain.Message{}, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	direction := -1
+	if q.Ascending {
+		direction = 1
+	}
+	// 主题和发件人按小写排序，排序键放在临时字段中，输出前移除
+	var key interface{} = "$received_at"
+	switch q.Sort {
+	case domain.MessageSortSubject:
+		key = bson.M{"$toLower": "$subject"}
+	case domain.MessageSortFrom:
+		key = bson.M{"$toLower": "$from"}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$addFields", Value: bson.M{"sort_key": key}}},
+		{{Key: "$sort", Value: sortBy("sort_key", direction, "seq", direction, "created_at", direction, "_id", direction)}},
+		{{Key: "$skip", Value: int64(max(q.Offset, 0))}},
+	}
+	if q.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: int64(q.Limit)}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$unset", Value: "sort_key"}})
+
+	cursor, err := s.c(&domain.Message{}).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	var messages []domain.Message
+	if err := s.all(ctx, cursor, &messages); err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	return &domain.MessagePage{Messages: messages, Total: int(total)}, nil
+}
+
+// matchHeaders 保存的头精确匹配（captured_headers 为子文档，按字段路径比较）
+func matchHeaders(filter bson.M, headers map[string]string) error {
+	for name, value := range headers {
+		// 头名只含字母、数字、连字符和下划线，可直接拼入字段路径
+		if !domain.ValidHeaderName(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		filter["captured_headers."+name] = value
+	}
+	return nil
+}
+
+// GetMessage 获取单封邮件
+func (s *Store) GetMessage(ctx context.Context, mailboxID, messageID string) (*domain.Message, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var message domain.Message
+	if err := s.findOne(ctx, &message, bson.M{"_id": messageID, "mailbox_id": mailboxID}, ErrMessageNotFound); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// MarkMessageRead 将邮件标记为已读
+func (s *Store) MarkMessageRead(ctx context.Context, mailboxID, messageID string) error {
+	return s.setMessageRead(ctx, mailboxID, messageID, true)
+}
+
+// MarkMessageUnread 将邮件标记为未读
+func (s *Store) MarkMessageUnread(ctx context.Context, mailboxID, messageID string) error {
+	return s.setMessageRead(ctx, mailboxID, messageID, false)
+}
+
+// setMessageRead 切换已读状态并同步邮箱未读数（只有状态确实变化时才更新统计）
+func (s *Store) setMessageRead(ctx context.Context, mailboxID, messageID string, read bool) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result, err := s.c(&domain.Message{}).UpdateOne(ctx,
+		bson.M{"_id": messageID, "mailbox_id": mailboxID, "is_read": !read},
+		bson.M{"$set": bson.M{"is_read": read}})
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return ErrMessageNotFound
+	}
+
+	delta := 1
+	if read {
+		delta = -1
+	}
+	_, err = s.c(&domain.Mailbox{}).UpdateOne(ctx, bson.M{"_id": mailboxID}, bson.M{"$inc": bson.M{"unread": delta}})
+	return err
+}
+
+// GetAttachment 获取邮件附件
+func (s *Store) GetAttachment(ctx context.Context, mailboxID, messageID, attachmentID string) (*domain.Attachment, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var attachment domain.Attachment
+	err := s.findOne(ctx, &attachment, bson.M{"_id": attachmentID, "message_id": messageID, "mailbox_id": mailboxID},
+		fmt.Errorf("attachment not found"))
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// DeleteMessage 删除单封邮件
+func (s *Store) DeleteMessage(ctx context.Context, mailboxID, messageID string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var message domain.Message
+	err := s.c(&message).FindOneAndDelete(ctx, bson.M{"_id": messageID, "mailbox_id": mailboxID},
+		options.FindOneAndDelete().SetProjection(bson.M{"is_read": 1})).Decode(&message)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := s.c(&domain.SearchDocument{}).DeleteMany(ctx, bson.M{"message_id": messageID}); err != nil {
+		return err
+	}
+
+	// 更新邮箱统计
+	inc := bson.M{"total_count": -1}
+	if !message.IsRead {
+		inc["unread"] = -1
+	}
+	_, err = s.c(&domain.Mailbox{}).UpdateOne(ctx, bson.M{"_id": mailboxID}, bson.M{"$inc": inc})
+	return err
+}
+
+// DeleteAllMessages 删除邮箱所有消息，返回删除数量
+func (s *Store) DeleteAllMessages(ctx context.Context, mailboxID string) (int, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var count int64
+	err := s.WithTransaction(func(tx *Store) error {
+		ctx := tx.bind(ctx)
+		result, err := tx.c(&domain.Message{}).DeleteMany(ctx, bson.M{"mailbox_id": mailboxID})
+		if err != nil {
+			return err
+		}
+		count = result.DeletedCount
+		if _, err := tx.c(&domain.SearchDocument{}).DeleteMany(ctx, bson.M{"mailbox_id": mailboxID}); err != nil {
+			return err
+		}
+		// 重置邮箱统计
+		_, err = tx.c(&domain.Mailbox{}).UpdateOne(ctx, bson.M{"_id": mailboxID},
+			bson.M{"$set": bson.M{"total_count": 0, "unread": 0}})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// SetMessageExpiry 设置单封邮件的删除时间
+func (s *Store) SetMessageExpiry(ctx context.Context, mailboxID, messageID string, expiresAt *time.Time) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	_, err := s.c(&domain.Message{}).UpdateOne(ctx, bson.M{"_id": messageID, "mailbox_id": mailboxID},
+		bson.M{"$set": bson.M{"expires_at": expiresAt}})
+	return err
+}
+
+// SetMessageQuarantined 将邮件移入或移出隔离区
+func (s *Store) SetMessageQuarantined(ctx context.Context, mailboxID, messageID string, quarantined bool) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result, err := s.c(&domain.Message{}).UpdateOne(ctx, bson.M{"_id": messageID, "mailbox_id": mailboxID},
+		bson.M{"$set": bson.M{"quarantined": quarantined}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredMessages 删除按规则到期的邮件，按邮箱分批删除并扣减统计
+func (s *Store) DeleteExpiredMessages(ctx context.Context, now time.Time) ([]domain.ExpiredMessages, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var rows []domain.Message
+	err := s.find(ctx, &rows, bson.M{"expires_at": bson.M{"$ne": nil, "$lte": now}}, options.Find().
+		SetProjection(bson.M{"_id": 1, "mailbox_id": 1}).
+		SetSort(sortBy("mailbox_id", 1, "_id", 1)))
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []domain.ExpiredMessages
+	for _, row := range rows {
+		if n := len(expired); n == 0 || expired[n-1].MailboxID != row.MailboxID {
+			expired = append(expired, domain.ExpiredMessages{MailboxID: row.MailboxID})
+		}
+		batch := &expired[len(expired)-1]
+		batch.MessageIDs = append(batch.MessageIDs, row.ID)
+	}
+
+	for i := range expired {
+		batch := &expired[i]
+		err := s.WithTransaction(func(tx *Store) error {
+			ctx := tx.bind(ctx)
+			messages := tx.c(&domain.Message{})
+			// 未读邮件单独删除，并发标记已读时不会重复扣减未读数
+			unread, err := messages.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": batch.MessageIDs}, "is_read": false})
+			if err != nil {
+				return err
+			}
+			read, err := messages.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": batch.MessageIDs}})
+			if err != nil {
+				return err
+			}
+			batch.Unread = int(unread.DeletedCount)
+			if _, err := tx.c(&domain.SearchDocument{}).DeleteMany(ctx, bson.M{"message_id": bson.M{"$in": batch.MessageIDs}}); err != nil {
+				return err
+			}
+			_, err = tx.c(&domain.Mailbox{}).UpdateOne(ctx, bson.M{"_id": batch.MailboxID}, bson.M{"$inc": bson.M{
+				"total_count": -(unread.DeletedCount + read.DeletedCount),
+				"unread":      -unread.DeletedCount,
+			}})
+			return err
+		})
+		if err != nil {
+			return expired[:i], err
+		}
+	}
+	return expired, nil
+}
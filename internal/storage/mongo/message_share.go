@@ -0,0 +1,65 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Message Share Repository ==========
+
+// SaveMessageShare 保存邮件分享链接（覆盖已有记录，随邮箱一起过期）
+func (s *Store) SaveMessageShare(ctx context.Context, share *domain.MessageShare) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	expiresAt, err := s.mailboxExpiry(ctx, share.MailboxID)
+	if err != nil {
+		return err
+	}
+	return s.upsert(ctx, bson.M{"_id": share.ID}, share, expiresWith(expiresAt))
+}
+
+// GetMessageShare 获取邮件分享链接
+func (s *Store) GetMessageShare(ctx context.Context, id string) (*domain.MessageShare, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var share domain.MessageShare
+	if err := s.findOne(ctx, &share, bson.M{"_id": id}, storage.ErrMessageShareNotFound); err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// ListMessageShares 列出邮箱的分享链接（按创建时间倒序）
+func (s *Store) ListMessageShares(ctx context.Context, mailboxID string) ([]*domain.MessageShare, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var shares []*domain.MessageShare
+	err := s.find(ctx, &shares, bson.M{"mailbox_id": mailboxID}, options.Find().SetSort(sortBy("created_at", -1, "_id", -1)))
+	if err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// RecordMessageShareView 访问次数加一并记录访问时间（$inc 原子更新，并发访问不丢计数）
+func (s *Store) RecordMessageShareView(ctx context.Context, id string, at time.Time) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result, err := s.c(&domain.MessageShare{}).UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$inc": bson.M{"views": 1},
+		"$set": bson.M{"last_viewed_at": at},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return storage.ErrMessageShareNotFound
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== OAuth Identity Repository ==========
+
+// CreateOAuthIdentity 关联第三方登录身份（依赖 (provider, subject) 唯一索引判断重复）
+func (s *Store) CreateOAuthIdentity(ctx context.Context, identity *domain.OAuthIdentity) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	if err := s.insert(ctx, identity); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return storage.ErrOAuthIdentityExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetOAuthIdentity 按提供方和提供方用户标识查找
+func (s *Store) GetOAuthIdentity(ctx context.Context, provider, subject string) (*domain.OAuthIdentity, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var identity domain.OAuthIdentity
+	err := s.findOne(ctx, &identity, bson.M{"provider": provider, "subject": subject}, storage.ErrOAuthIdentityNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// TouchOAuthIdentity 更新身份的最近登录时间
+func (s *Store) TouchOAuthIdentity(ctx context.Context, id string, at time.Time) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result, err := s.c(&domain.OAuthIdentity{}).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_login_at": at}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return storage.ErrOAuthIdentityNotFound
+	}
+	return nil
+}
+
+// ListOAuthIdentities 列出用户关联的第三方登录身份（按关联时间排序）
+func (s *Store) ListOAuthIdentities(ctx context.Context, userID string) ([]*domain.OAuthIdentity, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var identities []*domain.OAuthIdentity
+	if err := s.find(ctx, &identities, bson.M{"user_id": userID}, options.Find().SetSort(sortBy("created_at", 1))); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
@@ -0,0 +1,110 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Organization Repository ==========
+
+// CreateOrganization 创建组织
+func (s *Store) CreateOrganization(ctx context.Context, org *domain.Organization) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.insert(ctx, org)
+}
+
+// GetOrganization 获取组织
+func (s *Store) GetOrganization(ctx context.Context, id string) (*domain.Organization, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var org domain.Organization
+	if err := s.findOne(ctx, &org, bson.M{"_id": id}, storage.ErrOrganizationNotFound); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// SaveOrgMember 保存组织成员（已存在时只更新角色）
+func (s *Store) SaveOrgMember(ctx context.Context, member *domain.OrgMember) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	doc, err := s.encode(member)
+	if err != nil {
+		return err
+	}
+	insert := bson.D{}
+	for _, e := range doc {
+		if e.Key != "role" {
+			insert = append(insert, e)
+		}
+	}
+	_, err = s.c(member).UpdateOne(ctx,
+		bson.M{"org_id": member.OrgID, "user_id": member.UserID},
+		bson.M{"$set": bson.M{"role": member.Role}, "$setOnInsert": insert},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+// GetOrgMember 获取组织成员
+func (s *Store) GetOrgMember(ctx context.Context, orgID, userID string) (*domain.OrgMember, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var member domain.OrgMember
+	if err := s.findOne(ctx, &member, bson.M{"org_id": orgID, "user_id": userID}, storage.ErrOrgMemberNotFound); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ListOrgMembers 列出组织的所有成员
+func (s *Store) ListOrgMembers(ctx context.Context, orgID string) ([]*domain.OrgMember, error) {
+	return s.listOrgMembers(ctx, bson.M{"org_id": orgID})
+}
+
+// ListOrgMembershipsByUserID 列出用户加入的所有组织成员关系
+func (s *Store) ListOrgMembershipsByUserID(ctx context.Context, userID string) ([]*domain.OrgMember, error) {
+	return s.listOrgMembers(ctx, bson.M{"user_id": userID})
+}
+
+// listOrgMembers 按加入时间列出组织成员
+func (s *Store) listOrgMembers(ctx context.Context, filter bson.M) ([]*domain.OrgMember, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var members []*domain.OrgMember
+	err := s.find(ctx, &members, filter, options.Find().SetSort(sortBy("joined_at", 1)))
+	return members, err
+}
+
+// DeleteOrgMember 移除组织成员
+func (s *Store) DeleteOrgMember(ctx context.Context, orgID, userID string) error {
+	return s.deleteOne(ctx, &domain.OrgMember{}, bson.M{"org_id": orgID, "user_id": userID}, storage.ErrOrgMemberNotFound)
+}
+
+// SaveOrgInvite 保存组织邀请
+func (s *Store) SaveOrgInvite(ctx context.Context, invite *domain.OrgInvite) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.upsert(ctx, bson.M{"token": invite.Token}, invite)
+}
+
+// GetOrgInvite 根据加入令牌获取邀请
+func (s *Store) GetOrgInvite(ctx context.Context, token string) (*domain.OrgInvite, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var invite domain.OrgInvite
+	if err := s.findOne(ctx, &invite, bson.M{"token": token}, storage.ErrOrgInviteNotFound); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// DeleteOrgInvite 删除邀请（接受后作废，并发接受时只有一个成功）
+func (s *Store) DeleteOrgInvite(ctx context.Context, token string) error {
+	return s.deleteOne(ctx, &domain.OrgInvite{}, bson.M{"token": token}, storage.ErrOrgInviteNotFound)
+}
@@ -0,0 +1,128 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Outbox Repository ==========
+
+// SaveMessagesWithOutbox 在同一事务内批量插入邮件和发件箱事件（单机部署没有事务，先写邮件后写事件）
+func (s *Store) SaveMessagesWithOutbox(ctx context.Context, messages []*domain.Message, events []*domain.OutboxEvent) error {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return s.WithTransaction(func(tx *Store) error {
+		ctx := tx.bind(ctx)
+		if err := tx.saveMessages(ctx, messages); err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		docs := make([]interface{}, 0, len(events))
+		for _, event := range events {
+			doc, err := tx.encode(event)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, doc)
+		}
+		_, err := tx.c(&domain.OutboxEvent{}).InsertMany(ctx, docs)
+		return err
+	})
+}
+
+// claimableOutbox 在 now 时刻可认领的事件条件
+func claimableOutbox(now time.Time) bson.M {
+	return bson.M{
+		"processed_at": nil,
+		"$or": bson.A{
+			bson.M{"claimed_until": nil},
+			bson.M{"claimed_until": bson.M{"$lt": now}},
+		},
+	}
+}
+
+// ClaimOutboxEvents 认领可投递的事件
+//
+// 先选出候选，再按条件写入本次认领的令牌（单文档更新是原子的，并发认领时每条只有一方成功），
+// 最后按令牌读回认领到的事件。
+func (s *Store) ClaimOutboxEvents(ctx context.Context, now, until time.Time, limit int) ([]*domain.OutboxEvent, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	query := claimableOutbox(now)
+	query["next_attempt_at"] = bson.M{"$lte": now}
+	opts := options.Find().
+		SetSort(sortBy("created_at", 1, "_id", 1)).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"_id": 1})
+	var candidates []*domain.OutboxEvent
+	if err := s.find(ctx, &candidates, query, opts); err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(candidates))
+	for i, event := range candidates {
+		ids[i] = event.ID
+	}
+
+	token := uuid.NewString()
+	filter := claimableOutbox(now)
+	filter["_id"] = bson.M{"$in": ids}
+	result, err := s.c(&domain.OutboxEvent{}).UpdateMany(ctx, filter, bson.M{
+		"$set": bson.M{"claim_token": token, "claimed_until": until},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.ModifiedCount == 0 {
+		return nil, nil
+	}
+
+	var events []*domain.OutboxEvent
+	if err := s.find(ctx, &events, bson.M{"claim_token": token}, options.Find().SetSort(sortBy("created_at", 1, "_id", 1))); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// UpdateOutboxEvent 保存事件的投递进度
+func (s *Store) UpdateOutboxEvent(ctx context.Context, event *domain.OutboxEvent) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	_, err := s.c(event).UpdateOne(ctx, bson.M{"_id": event.ID}, bson.M{"$set": bson.M{
+		"delivered":       event.Delivered,
+		"attempts":        event.Attempts,
+		"last_error":      event.LastError,
+		"next_attempt_at": event.NextAttemptAt,
+		"claimed_until":   event.ClaimedUntil,
+		"processed_at":    event.ProcessedAt,
+	}})
+	return err
+}
+
+// DeleteProcessedOutboxEvents 删除早于 before 处理完成的事件
+func (s *Store) DeleteProcessedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	result, err := s.c(&domain.OutboxEvent{}).DeleteMany(ctx, bson.M{"processed_at": bson.M{"$ne": nil, "$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
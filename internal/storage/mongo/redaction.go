@@ -0,0 +1,35 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Redaction Repository ==========
+
+// SaveMessageRedaction 保存邮件脱敏副本（覆盖已有副本，随邮箱一起过期）
+func (s *Store) SaveMessageRedaction(ctx context.Context, redaction *domain.MessageRedaction) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	expiresAt, err := s.mailboxExpiry(ctx, redaction.MailboxID)
+	if err != nil {
+		return err
+	}
+	return s.upsert(ctx, bson.M{"message_id": redaction.MessageID}, redaction, expiresWith(expiresAt))
+}
+
+// GetMessageRedaction 获取邮件脱敏副本
+func (s *Store) GetMessageRedaction(ctx context.Context, mailboxID, messageID string) (*domain.MessageRedaction, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var redaction domain.MessageRedaction
+	err := s.findOne(ctx, &redaction, bson.M{"message_id": messageID, "mailbox_id": mailboxID}, storage.ErrRedactionNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return &redaction, nil
+}
@@ -0,0 +1,61 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Reserved Prefix Repository ==========
+
+// CreateReservedPrefix 添加保留前缀（依赖 (domain, local_part) 唯一索引判断重复）
+func (s *Store) CreateReservedPrefix(ctx context.Context, prefix *domain.ReservedPrefix) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	if err := s.insert(ctx, prefix); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return storage.ErrReservedPrefixExists
+		}
+		return err
+	}
+	return nil
+}
+
+// ListReservedPrefixes 列出保留前缀（按域名、前缀排序）
+func (s *Store) ListReservedPrefixes(ctx context.Context, userID *string) ([]*domain.ReservedPrefix, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	filter := bson.M{}
+	if userID != nil {
+		filter["user_id"] = *userID
+	}
+	var prefixes []*domain.ReservedPrefix
+	if err := s.find(ctx, &prefixes, filter, options.Find().SetSort(sortBy("domain", 1, "local_part", 1))); err != nil {
+		return nil, err
+	}
+	return prefixes, nil
+}
+
+// FindReservedPrefixes 查找前缀在指定域名及所有系统域名上的条目
+func (s *Store) FindReservedPrefixes(ctx context.Context, domainName, localPart string) ([]*domain.ReservedPrefix, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var prefixes []*domain.ReservedPrefix
+	filter := bson.M{"local_part": localPart, "domain": bson.M{"$in": bson.A{"", domainName}}}
+	if err := s.find(ctx, &prefixes, filter); err != nil {
+		return nil, err
+	}
+	return prefixes, nil
+}
+
+// DeleteReservedPrefix 删除保留前缀
+func (s *Store) DeleteReservedPrefix(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.deleteOneContext(ctx, &domain.ReservedPrefix{}, bson.M{"_id": id}, storage.ErrReservedPrefixNotFound)
+}
@@ -0,0 +1,129 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// termSetField 索引文档中按词项拆开的数组（多键索引，查询用 $all 匹配全部词项）
+const termSetField = "term_set"
+
+// IndexMessages 写入或覆盖邮件的索引文档（随邮箱一起过期）
+func (s *Store) IndexMessages(ctx context.Context, docs []*domain.SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	expiry, err := s.mailboxExpiries(ctx, docs)
+	if err != nil {
+		return err
+	}
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for _, doc := range docs {
+		set, err := s.fields(doc,
+			bson.E{Key: termSetField, Value: strings.Fields(doc.Terms)},
+			expiresWith(expiry[doc.MailboxID]))
+		if err != nil {
+			return err
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"message_id": doc.MessageID}).
+			SetUpdate(bson.M{"$set": set}).
+			SetUpsert(true))
+	}
+	_, err = s.c(&domain.SearchDocument{}).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// mailboxExpiries 索引文档所属邮箱的过期时间
+func (s *Store) mailboxExpiries(ctx context.Context, docs []*domain.SearchDocument) (map[string]*time.Time, error) {
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.MailboxID)
+	}
+	var mailboxes []domain.Mailbox
+	err := s.find(ctx, &mailboxes, bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"expires_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	expiry := make(map[string]*time.Time, len(mailboxes))
+	for _, mailbox := range mailboxes {
+		expiry[mailbox.ID] = mailbox.ExpiresAt
+	}
+	return expiry, nil
+}
+
+// SearchIndex 查找命中全部词项的邮件
+//
+// 词项数组走多键索引；与 messages 关联，已删除和隔离区中的邮件不返回。
+func (s *Store) SearchIndex(ctx context.Context, query domain.IndexQuery) (*domain.MessageSearchResult, error) {
+	query.Normalize()
+	result := &domain.MessageSearchResult{Messages: []domain.Message{}, Page: query.Page, PageSize: query.PageSize}
+	if len(query.Terms) == 0 || len(query.MailboxIDs) == 0 {
+		return result, nil
+	}
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	cursor, err := s.c(&domain.SearchDocument{}).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"mailbox_id": bson.M{"$in": query.MailboxIDs},
+			termSetField: bson.M{"$all": query.Terms},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         collectionOf(&domain.Message{}),
+			"localField":   "message_id",
+			"foreignField": "_id",
+			"as":           "message",
+		}}},
+		{{Key: "$unwind", Value: "$message"}},
+		{{Key: "$match", Value: bson.M{"message.quarantined": false}}},
+		{{Key: "$facet", Value: bson.M{
+			"total": bson.A{bson.M{"$count": "n"}},
+			"page": bson.A{
+				bson.M{"$sort": sortBy("received_at", -1, "message_id", -1)},
+				bson.M{"$skip": int64((query.Page - 1) * query.PageSize)},
+				bson.M{"$limit": int64(query.PageSize)},
+				bson.M{"$replaceRoot": bson.M{"newRoot": "$message"}},
+			},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+	var facets []struct {
+		Total []struct {
+			N int `bson:"n"`
+		} `bson:"total"`
+		Page []domain.Message `bson:"page"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+	if len(facets) == 0 || len(facets[0].Total) == 0 {
+		return result, nil
+	}
+	result.Messages = facets[0].Page
+	result.Total = facets[0].Total[0].N
+	result.TotalPages = (result.Total + query.PageSize - 1) / query.PageSize
+	return result, nil
+}
+
+// ClearSearchIndex 清空索引
+func (s *Store) ClearSearchIndex(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	_, err := s.c(&domain.SearchDocument{}).DeleteMany(ctx, bson.M{})
+	return err
+}
@@ -0,0 +1,292 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// SearchMessages 搜索邮件（主题和发件人不区分大小写的包含匹配，与 SQL 存储的 LIKE 一致）
+//
+// 不生成命中摘要，开启高亮时由服务层的 Go 提取器处理。
+func (s *Store) SearchMessages(ctx context.Context, criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	// 设置默认分页参数
+	if criteria.Page <= 0 {
+		criteria.Page = 1
+	}
+	if criteria.PageSize <= 0 {
+		criteria.PageSize = 20
+	}
+	if criteria.PageSize > 100 {
+		criteria.PageSize = 100
+	}
+
+	filter := bson.M{"mailbox_id": criteria.MailboxID}
+	var and bson.A
+
+	// 关键词搜索（正文保存在文件系统中，数据库只能匹配主题和发件人）
+	if criteria.Query != "" {
+		pattern := containsPattern(criteria.Query)
+		and = append(and, bson.M{"$or": bson.A{bson.M{"subject": pattern}, bson.M{"from": pattern}}})
+	}
+	if criteria.From != "" {
+		filter["from"] = containsPattern(criteria.From)
+	}
+	if criteria.Subject != "" {
+		filter["subject"] = containsPattern(criteria.Subject)
+	}
+
+	// 时间范围筛选
+	if criteria.StartDate != nil || criteria.EndDate != nil {
+		created := bson.M{}
+		if criteria.StartDate != nil {
+			created["$gte"] = *criteria.StartDate
+		}
+		if criteria.EndDate != nil {
+			created["$lte"] = *criteria.EndDate
+		}
+		filter["created_at"] = created
+	}
+
+	if criteria.IsRead != nil {
+		filter["is_read"] = *criteria.IsRead
+	}
+
+	// 附件筛选：附件集合中出现过的邮件ID
+	if criteria.HasAttachment != nil {
+		withAttachments, err := s.c(&domain.Attachment{}).Distinct(ctx, "message_id", bson.M{"mailbox_id": criteria.MailboxID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search attachments: %w", err)
+		}
+		if *criteria.HasAttachment {
+			and = append(and, bson.M{"_id": bson.M{"$in": withAttachments}})
+		} else {
+			and = append(and, bson.M{"_id": bson.M{"$nin": withAttachments}})
+		}
+	}
+
+	if criteria.Language != "" {
+		filter["detected_language"] = strings.ToLower(criteria.Language)
+	}
+	if criteria.SpamScoreGte != nil {
+		filter["spam_score"] = bson.M{"$gte": *criteria.SpamScoreGte}
+	}
+	if criteria.IsSpam != nil {
+		filter["is_spam"] = *criteria.IsSpam
+	}
+	if criteria.DKIMResult != "" {
+		filter["dkim_result"] = criteria.DKIMResult
+	}
+	if err := matchHeaders(filter, criteria.Headers); err != nil {
+		return nil, err
+	}
+	if len(and) > 0 {
+		filter["$and"] = and
+	}
+
+	total, err := s.count(ctx, &domain.Message{}, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	var messages []domain.Message
+	err = s.find(ctx, &messages, filter, options.Find().
+		SetSort(sortBy("seq", -1, "created_at", -1)).
+		SetSkip(int64((criteria.Page-1)*criteria.PageSize)).
+		SetLimit(int64(criteria.PageSize)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+
+	// 计算总页数
+	totalPages := int(total) / criteria.PageSize
+	if int(total)%criteria.PageSize > 0 {
+		totalPages++
+	}
+
+	return &domain.MessageSearchResult{
+		Messages:   messages,
+		Total:      int(total),
+		Page:       criteria.Page,
+		PageSize:   criteria.PageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// containsPattern 不区分大小写的包含匹配（关键词按字面量匹配）
+func containsPattern(keyword string) primitive.Regex {
+	return primitive.Regex{Pattern: regexp.QuoteMeta(keyword), Options: "i"}
+}
+
+// ========== Webhook Repository ==========
+
+// CreateWebhook 创建 Webhook（邮箱 Webhook 随邮箱一起过期）
+func (s *Store) CreateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	if !webhook.MailboxScoped() {
+		return s.insert(ctx, webhook)
+	}
+	expiresAt, err := s.mailboxExpiry(ctx, webhook.OwnerID)
+	if err != nil {
+		return err
+	}
+	return s.insert(ctx, webhook, expiresWith(expiresAt))
+}
+
+// GetWebhook 获取 Webhook
+func (s *Store) GetWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var webhook domain.Webhook
+	if err := s.findOne(ctx, &webhook, bson.M{"_id": id}, fmt.Errorf("webhook not found")); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks 列出用户的 Webhooks（不含邮箱 Webhook）
+func (s *Store) ListWebhooks(ctx context.Context, userID string) ([]domain.Webhook, error) {
+	return s.listWebhooks(ctx, bson.M{"user_id": userID, "owner_type": bson.M{"$ne": domain.WebhookOwnerMailbox}}, -1)
+}
+
+// ListWebhooksByMailbox 列出邮箱 Webhooks
+func (s *Store) ListWebhooksByMailbox(ctx context.Context, mailboxID string) ([]domain.Webhook, error) {
+	return s.listWebhooks(ctx, bson.M{"owner_type": domain.WebhookOwnerMailbox, "owner_id": mailboxID}, 1)
+}
+
+// ListWebhooksByOrgID 列出组织的所有 Webhook
+func (s *Store) ListWebhooksByOrgID(ctx context.Context, orgID string) ([]domain.Webhook, error) {
+	return s.listWebhooks(ctx, bson.M{"org_id": orgID}, -1)
+}
+
+// listWebhooks 按条件和创建时间顺序列出 Webhook
+func (s *Store) listWebhooks(ctx context.Context, filter bson.M, direction int) ([]domain.Webhook, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var webhooks []domain.Webhook
+	if err := s.find(ctx, &webhooks, filter, options.Find().SetSort(sortBy("created_at", direction))); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook 更新 Webhook
+func (s *Store) UpdateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.upsert(ctx, bson.M{"_id": webhook.ID}, webhook)
+}
+
+// DeleteWebhook 删除 Webhook 及其投递记录
+func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.WithTransaction(func(tx *Store) error {
+		ctx := tx.bind(ctx)
+		if _, err := tx.c(&domain.WebhookDelivery{}).DeleteMany(ctx, bson.M{"webhook_id": id}); err != nil {
+			return err
+		}
+		_, err := tx.c(&domain.Webhook{}).DeleteOne(ctx, bson.M{"_id": id})
+		return err
+	})
+}
+
+// RecordDelivery 记录投递（邮箱 Webhook 的投递记录随邮箱一起过期）
+func (s *Store) RecordDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var webhook domain.Webhook
+	err := s.findOne(ctx, &webhook, bson.M{"_id": delivery.WebhookID}, nil,
+		options.FindOne().SetProjection(bson.M{"owner_type": 1, "owner_id": 1}))
+	if err != nil {
+		return err
+	}
+	if !webhook.MailboxScoped() {
+		return s.insert(ctx, delivery)
+	}
+	expiresAt, err := s.mailboxExpiry(ctx, webhook.OwnerID)
+	if err != nil {
+		return err
+	}
+	return s.insert(ctx, delivery, expiresWith(expiresAt))
+}
+
+// GetDeliveries 获取投递记录
+func (s *Store) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	return s.listDeliveries(ctx, bson.M{"webhook_id": webhookID}, options.Find().
+		SetSort(sortBy("created_at", -1)).
+		SetLimit(int64(limit)))
+}
+
+// GetPendingDeliveries 获取待重试投递
+func (s *Store) GetPendingDeliveries(ctx context.Context, limit int) ([]domain.WebhookDelivery, error) {
+	filter := bson.M{"success": false, "next_retry": bson.M{"$ne": nil, "$lte": time.Now().UTC()}}
+	return s.listDeliveries(ctx, filter, options.Find().
+		SetSort(sortBy("next_retry", 1)).
+		SetLimit(int64(limit)))
+}
+
+// ListDeadLetters 列出尚未重新投递的死信（webhookID 为空时列出全部）
+func (s *Store) ListDeadLetters(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	filter := bson.M{"dead_letter": true, "redelivered_at": nil}
+	if webhookID != "" {
+		filter["webhook_id"] = webhookID
+	}
+	return s.listDeliveries(ctx, filter, options.Find().
+		SetSort(sortBy("created_at", -1)).
+		SetLimit(int64(limit)))
+}
+
+// listDeliveries 按条件列出投递记录
+func (s *Store) listDeliveries(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]domain.WebhookDelivery, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var deliveries []domain.WebhookDelivery
+	if err := s.find(ctx, &deliveries, filter, opts); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// CancelPendingDeliveries 取消指定邮箱尚未完成的重试投递，返回取消数量
+func (s *Store) CancelPendingDeliveries(ctx context.Context, mailboxID string) (int, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	result, err := s.c(&domain.WebhookDelivery{}).UpdateMany(ctx,
+		bson.M{"mailbox_id": mailboxID, "success": false, "next_retry": bson.M{"$ne": nil}},
+		bson.M{"$set": bson.M{"next_retry": nil}})
+	if err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
+
+// GetDelivery 获取投递记录
+func (s *Store) GetDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var delivery domain.WebhookDelivery
+	if err := s.findOne(ctx, &delivery, bson.M{"_id": id}, fmt.Errorf("webhook delivery not found")); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// UpdateDelivery 更新投递记录
+func (s *Store) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.upsert(ctx, bson.M{"_id": delivery.ID}, delivery)
+}
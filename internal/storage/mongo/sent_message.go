@@ -0,0 +1,64 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Sent Message Repository ==========
+
+// SaveSentMessage 保存已发送邮件（随邮箱一起过期）
+func (s *Store) SaveSentMessage(ctx context.Context, message *domain.SentMessage) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	expiresAt, err := s.mailboxExpiry(ctx, message.MailboxID)
+	if err != nil {
+		return err
+	}
+	return s.insert(ctx, message, expiresWith(expiresAt))
+}
+
+// ListSentMessages 列出邮箱发出的邮件（按发送时间倒序）
+func (s *Store) ListSentMessages(ctx context.Context, mailboxID string) ([]*domain.SentMessage, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var messages []*domain.SentMessage
+	err := s.find(ctx, &messages, bson.M{"mailbox_id": mailboxID}, options.Find().SetSort(sortBy("sent_at", -1, "_id", -1)))
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// CountSentMessages 按邮箱或用户统计 Since 之后发出的邮件数及其中最早的发送时间
+func (s *Store) CountSentMessages(ctx context.Context, filter domain.SentMessageFilter) (domain.SentMessageUsage, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	query := bson.M{"sent_at": bson.M{"$gt": filter.Since}}
+	if filter.MailboxID != "" {
+		query["mailbox_id"] = filter.MailboxID
+	}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+
+	var usage domain.SentMessageUsage
+	count, err := s.count(ctx, &domain.SentMessage{}, query)
+	if err != nil || count == 0 {
+		return usage, err
+	}
+	var oldest domain.SentMessage
+	err = s.findOne(ctx, &oldest, query, nil, options.FindOne().
+		SetSort(sortBy("sent_at", 1)).
+		SetProjection(bson.M{"sent_at": 1}))
+	if err != nil {
+		return domain.SentMessageUsage{}, err
+	}
+	usage.Count = count
+	usage.OldestAt = oldest.SentAt
+	return usage, nil
+}
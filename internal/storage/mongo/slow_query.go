@@ -0,0 +1,69 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+
+	"tempmail/backend/internal/storage/postgres"
+)
+
+// SetSlowQueryLog 开启慢命令记录：耗时超过 threshold 的命令交给 sink
+//
+// 记录的语句形如 "find messages"（命令名和集合名），不含查询条件的值。
+func (s *Store) SetSlowQueryLog(threshold time.Duration, sink postgres.SlowQuerySink) {
+	s.monitor.config.Store(&slowQueryConfig{threshold: threshold, sink: sink})
+}
+
+// slowQueryConfig 慢命令阈值和接收方
+type slowQueryConfig struct {
+	threshold time.Duration
+	sink      postgres.SlowQuerySink
+}
+
+// slowQueryMonitor 驱动的命令监听器：连接建立时注册，SetSlowQueryLog 之后才开始记录
+type slowQueryMonitor struct {
+	config  atomic.Pointer[slowQueryConfig]
+	started sync.Map // RequestID -> 命令描述
+}
+
+func (m *slowQueryMonitor) commandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, evt *event.CommandStartedEvent) {
+			if m.config.Load() == nil {
+				return
+			}
+			statement := evt.CommandName
+			// 命令文档的第一个字段是命令名，值为集合名（如 {find: "messages", ...}）
+			if elements, err := evt.Command.Elements(); err == nil && len(elements) > 0 {
+				if collection, ok := elements[0].Value().StringValueOK(); ok {
+					statement += " " + collection
+				}
+			}
+			m.started.Store(evt.RequestID, statement)
+		},
+		Succeeded: func(_ context.Context, evt *event.CommandSucceededEvent) {
+			m.finish(evt.RequestID, evt.Duration, nil)
+		},
+		Failed: func(_ context.Context, evt *event.CommandFailedEvent) {
+			m.finish(evt.RequestID, evt.Duration, errors.New(evt.Failure))
+		},
+	}
+}
+
+// finish 命令结束时只在超过阈值时交给 sink
+func (m *slowQueryMonitor) finish(requestID int64, elapsed time.Duration, err error) {
+	statement, ok := m.started.LoadAndDelete(requestID)
+	if !ok {
+		return
+	}
+	config := m.config.Load()
+	if config == nil || elapsed < config.threshold {
+		return
+	}
+	config.sink.RecordSlowQuery(statement.(string), elapsed, err)
+}
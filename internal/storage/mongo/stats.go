@@ -0,0 +1,104 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"tempmail/backend/internal/domain"
+)
+
+// GetMessageStats 统计邮件收件情况
+//
+// 一次聚合在 [Since, Until) 窗口内用 $facet 同时得到汇总、小时分布、发件人排行和按邮箱计数，
+// 走 (mailbox_id, received_at) 复合索引；小时桶由 $dateTrunc 按 UTC 截断（需要 MongoDB 5.0+）。
+func (s *Store) GetMessageStats(ctx context.Context, query domain.MessageStatsQuery) (*domain.MessageStats, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	stats := &domain.MessageStats{
+		Since:      query.Since,
+		Until:      query.Until,
+		Hourly:     []domain.HourlyCount{},
+		TopSenders: []domain.SenderCount{},
+		ByMailbox:  make(map[string]int),
+	}
+	if len(query.MailboxIDs) == 0 {
+		return stats, nil
+	}
+
+	senders := bson.A{
+		bson.M{"$group": bson.M{"_id": bson.M{"$toLower": "$from"}, "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": sortBy("count", -1, "_id", 1)},
+	}
+	if query.TopSenders > 0 {
+		senders = append(senders, bson.M{"$limit": int64(query.TopSenders)})
+	}
+	cursor, err := s.c(&domain.Message{}).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"mailbox_id":  bson.M{"$in": query.MailboxIDs},
+			"received_at": bson.M{"$gte": query.Since, "$lt": query.Until},
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"totals": bson.A{bson.M{"$group": bson.M{
+				"_id":      nil,
+				"total":    bson.M{"$sum": 1},
+				"unread":   bson.M{"$sum": bson.M{"$cond": bson.A{"$is_read", 0, 1}}},
+				"avg_size": bson.M{"$avg": "$size"},
+			}}},
+			"hourly": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"$dateTrunc": bson.M{"date": "$received_at", "unit": "hour", "timezone": "UTC"}},
+					"count": bson.M{"$sum": 1},
+				}},
+				bson.M{"$sort": sortBy("_id", 1)},
+			},
+			"senders":    senders,
+			"by_mailbox": bson.A{bson.M{"$group": bson.M{"_id": "$mailbox_id", "count": bson.M{"$sum": 1}}}},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate message stats: %w", err)
+	}
+
+	type group struct {
+		Key   string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	var facets []struct {
+		Totals []struct {
+			Total   int     `bson:"total"`
+			Unread  int     `bson:"unread"`
+			AvgSize float64 `bson:"avg_size"`
+		} `bson:"totals"`
+		Hourly []struct {
+			Hour  time.Time `bson:"_id"`
+			Count int       `bson:"count"`
+		} `bson:"hourly"`
+		Senders   []group `bson:"senders"`
+		ByMailbox []group `bson:"by_mailbox"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, fmt.Errorf("failed to aggregate message stats: %w", err)
+	}
+	if len(facets) == 0 || len(facets[0].Totals) == 0 {
+		return stats, nil
+	}
+
+	result := facets[0]
+	stats.Total = result.Totals[0].Total
+	stats.Unread = result.Totals[0].Unread
+	stats.AverageSize = int64(result.Totals[0].AvgSize)
+	for _, row := range result.Hourly {
+		stats.Hourly = append(stats.Hourly, domain.HourlyCount{Hour: row.Hour.UTC(), Count: row.Count})
+	}
+	for _, row := range result.Senders {
+		stats.TopSenders = append(stats.TopSenders, domain.SenderCount{Sender: row.Key, Count: row.Count})
+	}
+	for _, row := range result.ByMailbox {
+		stats.ByMailbox[row.Key] = row.Count
+	}
+	return stats, nil
+}
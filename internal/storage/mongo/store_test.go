@@ -0,0 +1,231 @@
+package mongo
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodrv "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// newTestStore 连接 TEMPMAIL_TEST_MONGO_URI 指定的服务器，未设置时跳过
+//
+// 每个测试使用独立的数据库，结束时删除。
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	uri := os.Getenv("TEMPMAIL_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEMPMAIL_TEST_MONGO_URI not set")
+	}
+	store, err := NewStore(uri)
+	require.NoError(t, err)
+	store.db = store.client.Database("tempmail_test_" + uuid.NewString()[:8])
+	t.Cleanup(func() {
+		_ = store.db.Drop(context.Background())
+		_ = store.Close()
+	})
+	require.NoError(t, store.ensureIndexes(t.Context()))
+	return store
+}
+
+// newTestMailbox 保存一个测试邮箱
+func newTestMailbox(t *testing.T, store *Store, expiresAt *time.Time) *domain.Mailbox {
+	t.Helper()
+	suffix := uuid.NewString()[:8]
+	mailbox := &domain.Mailbox{
+		ID: uuid.NewString(), Address: "box-" + suffix + "@corp.example", LocalPart: "box-" + suffix,
+		Domain: "corp.example", Token: "tok-" + suffix, CreatedAt: time.Now(), ExpiresAt: expiresAt,
+	}
+	require.NoError(t, store.CreateMailbox(t.Context(), mailbox))
+	return mailbox
+}
+
+// ttlSeconds 集合中按 key 建立的索引的 expireAfterSeconds（没有 TTL 时为 -1）
+func ttlSeconds(t *testing.T, coll *mongodrv.Collection, key string) int32 {
+	t.Helper()
+	cursor, err := coll.Indexes().List(t.Context())
+	require.NoError(t, err)
+	var specs []struct {
+		Key                bson.D `bson:"key"`
+		ExpireAfterSeconds *int32 `bson:"expireAfterSeconds"`
+	}
+	require.NoError(t, cursor.All(t.Context(), &specs))
+	for _, spec := range specs {
+		if len(spec.Key) == 1 && spec.Key[0].Key == key && spec.ExpireAfterSeconds != nil {
+			return *spec.ExpireAfterSeconds
+		}
+	}
+	return -1
+}
+
+func TestRegistry_UsesGormColumnNames(t *testing.T) {
+	registry, err := newRegistry()
+	require.NoError(t, err)
+
+	userID := "user-1"
+	raw, err := bson.MarshalWithRegistry(registry, &domain.Mailbox{
+		ID: "mb-1", Address: "a@example.com", LocalPart: "a", UserID: &userID,
+	})
+	require.NoError(t, err)
+
+	var doc bson.M
+	require.NoError(t, bson.Unmarshal(raw, &doc))
+	assert.Equal(t, "mb-1", doc["_id"])
+	assert.Equal(t, "a", doc["local_part"])
+	assert.Equal(t, "user-1", doc["user_id"])
+	assert.NotContains(t, doc, "id")
+
+	var decoded domain.Mailbox
+	require.NoError(t, bson.UnmarshalWithRegistry(registry, raw, &decoded))
+	assert.Equal(t, "mb-1", decoded.ID)
+	assert.Equal(t, "a@example.com", decoded.Address)
+}
+
+func TestRegistry_SkipsFileSystemContent(t *testing.T) {
+	registry, err := newRegistry()
+	require.NoError(t, err)
+
+	raw, err := bson.MarshalWithRegistry(registry, &domain.Message{ID: "msg-1", Subject: "hi", Text: "body"})
+	require.NoError(t, err)
+
+	var doc bson.M
+	require.NoError(t, bson.Unmarshal(raw, &doc))
+	assert.Equal(t, "hi", doc["subject"])
+	assert.NotContains(t, doc, "text")
+}
+
+func TestCollectionOf_MatchesTableNames(t *testing.T) {
+	assert.Equal(t, "mailboxes", collectionOf(&domain.Mailbox{}))
+	assert.Equal(t, "mailboxes", collectionOf(&[]domain.Mailbox{}))
+	assert.Equal(t, domain.SearchDocument{}.TableName(), collectionOf(&domain.SearchDocument{}))
+}
+
+func TestStore_ExpiredMailboxHiddenBeforeTTLRuns(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	expired := time.Now().Add(-time.Minute)
+	mailbox := newTestMailbox(t, store, &expired)
+
+	// TTL 索引在宽限期后才删除，期间读取按过期处理，清理任务能列出它并发送 mailbox.expired
+	_, err := store.GetMailbox(ctx, mailbox.ID)
+	assert.ErrorIs(t, err, storage.ErrMailboxNotFound)
+	_, err = store.GetMailboxByAddress(ctx, mailbox.Address)
+	assert.ErrorIs(t, err, storage.ErrMailboxNotFound)
+
+	listed, err := store.ListExpiredMailboxes(ctx, time.Now())
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, mailbox.ID, listed[0].ID)
+}
+
+func TestStore_MessagesCountedAndSequenced(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+	mailbox := newTestMailbox(t, store, nil)
+
+	var ids []string
+	for i := range 3 {
+		message := &domain.Message{
+			ID: uuid.NewString(), MailboxID: mailbox.ID, From: "a@example.com", To: mailbox.Address,
+			Subject: "msg", ReceivedAt: time.Now().Add(time.Duration(i) * time.Second),
+		}
+		require.NoError(t, store.SaveMessage(ctx, message))
+		assert.Equal(t, int64(i+1), message.Seq)
+		ids = append(ids, message.ID)
+	}
+
+	got, err := store.GetMailbox(ctx, mailbox.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.TotalCount)
+	assert.Equal(t, 3, got.Unread)
+
+	require.NoError(t, store.MarkMessageRead(ctx, mailbox.ID, ids[0]))
+	require.NoError(t, store.DeleteMessage(ctx, mailbox.ID, ids[1]))
+	got, err = store.GetMailbox(ctx, mailbox.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.TotalCount)
+	assert.Equal(t, 1, got.Unread)
+
+	_, err = store.GetMessage(ctx, mailbox.ID, ids[1])
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+	messages, err := store.ListMessages(ctx, mailbox.ID)
+	require.NoError(t, err)
+	assert.Len(t, messages, 2)
+}
+
+func TestStore_AddressTakenByMailboxOrAlias(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+	mailbox := newTestMailbox(t, store, nil)
+
+	duplicate := *mailbox
+	duplicate.ID, duplicate.Token = uuid.NewString(), "other-token"
+	assert.ErrorIs(t, store.CreateMailbox(ctx, &duplicate), storage.ErrAddressTaken)
+
+	alias := &domain.MailboxAlias{ID: uuid.NewString(), MailboxID: mailbox.ID, Address: "alias@corp.example", IsActive: true}
+	require.NoError(t, store.SaveAlias(ctx, alias))
+	taken := &domain.Mailbox{ID: uuid.NewString(), Address: alias.Address, LocalPart: "alias", Domain: "corp.example", Token: "tok-alias"}
+	assert.ErrorIs(t, store.CreateMailbox(ctx, taken), storage.ErrAddressTaken)
+}
+
+func TestStore_DeleteExpiredMailboxesRemovesChildren(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	expired := time.Now().Add(-time.Minute)
+	mailbox := newTestMailbox(t, store, &expired)
+	live := newTestMailbox(t, store, nil)
+	for _, owner := range []*domain.Mailbox{mailbox, live} {
+		require.NoError(t, store.SaveMessage(ctx, &domain.Message{
+			ID: uuid.NewString(), MailboxID: owner.ID, From: "a@example.com", To: owner.Address, ReceivedAt: time.Now(),
+		}))
+	}
+	alias := &domain.MailboxAlias{ID: uuid.NewString(), MailboxID: mailbox.ID, Address: "gone@corp.example", IsActive: true}
+	require.NoError(t, store.SaveAlias(ctx, alias))
+
+	count, err := store.DeleteExpiredMailboxes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	messages, err := store.ListMessages(ctx, mailbox.ID)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+	_, err = store.GetAlias(ctx, alias.ID)
+	assert.ErrorIs(t, err, ErrAliasNotFound)
+
+	messages, err = store.ListMessages(ctx, live.ID)
+	require.NoError(t, err)
+	assert.Len(t, messages, 1)
+}
+
+func TestStore_TTLIndexesKeepGracePeriod(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+	grace := int32(mailboxTTLGrace.Seconds())
+
+	mailboxes := store.c(&domain.Mailbox{})
+	assert.Equal(t, grace, ttlSeconds(t, mailboxes, "expires_at"))
+	assert.Equal(t, grace, ttlSeconds(t, store.c(&domain.Message{}), mailboxExpiresField))
+
+	// 旧版本到期即删的 TTL 索引在启动时改为宽限期
+	_, err := mailboxes.Indexes().DropOne(ctx, "expires_at_1")
+	require.NoError(t, err)
+	_, err = mailboxes.Indexes().CreateOne(ctx, mongodrv.IndexModel{
+		Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(0), ttlSeconds(t, mailboxes, "expires_at"))
+
+	require.NoError(t, store.ensureIndexes(ctx))
+	assert.Equal(t, grace, ttlSeconds(t, mailboxes, "expires_at"))
+}
@@ -0,0 +1,203 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Tag Repository ==========
+
+// CreateTag 创建标签
+func (s *Store) CreateTag(ctx context.Context, tag *domain.Tag) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	if tag.ID == "" {
+		tag.ID = uuid.New().String()
+	}
+
+	// 如果时间戳为零值，则设置为当前时间（数据迁移时保留原值）
+	now := time.Now().UTC()
+	if tag.CreatedAt.IsZero() {
+		tag.CreatedAt = now
+	}
+	if tag.UpdatedAt.IsZero() {
+		tag.UpdatedAt = now
+	}
+
+	return s.insert(ctx, tag)
+}
+
+// GetTag 根据ID获取标签
+func (s *Store) GetTag(ctx context.Context, id string) (*domain.Tag, error) {
+	return s.getTag(ctx, bson.M{"_id": id})
+}
+
+// GetTagByName 根据用户ID和标签名获取标签
+func (s *Store) GetTagByName(ctx context.Context, userID, name string) (*domain.Tag, error) {
+	return s.getTag(ctx, bson.M{"user_id": userID, "name": name})
+}
+
+// getTag 按条件获取一个标签
+func (s *Store) getTag(ctx context.Context, filter bson.M) (*domain.Tag, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var tag domain.Tag
+	if err := s.findOne(ctx, &tag, filter, fmt.Errorf("tag not found")); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// ListTags 列出用户的所有标签（带使用计数）
+func (s *Store) ListTags(ctx context.Context, userID string) ([]domain.TagWithCount, error) {
+	return s.listTagsWithCount(ctx, bson.M{"user_id": userID})
+}
+
+// ListTagsByOrgID 列出组织的所有标签（带邮件计数）
+func (s *Store) ListTagsByOrgID(ctx context.Context, orgID string) ([]domain.TagWithCount, error) {
+	return s.listTagsWithCount(ctx, bson.M{"org_id": orgID})
+}
+
+// listTagsWithCount 按条件列出标签，并统计每个标签关联的邮件数
+func (s *Store) listTagsWithCount(ctx context.Context, filter bson.M) ([]domain.TagWithCount, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	cursor, err := s.c(&domain.Tag{}).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         collectionOf(&domain.MessageTag{}),
+			"localField":   "_id",
+			"foreignField": "tag_id",
+			"as":           "tagged",
+		}}},
+		{{Key: "$addFields", Value: bson.M{"message_count": bson.M{"$size": "$tagged"}}}},
+		{{Key: "$unset", Value: "tagged"}},
+		{{Key: "$sort", Value: sortBy("created_at", -1)}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var results []domain.TagWithCount
+	err = s.all(ctx, cursor, &results)
+	return results, err
+}
+
+// UpdateTag 更新标签
+func (s *Store) UpdateTag(ctx context.Context, tag *domain.Tag) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	tag.UpdatedAt = time.Now().UTC()
+	return s.upsert(ctx, bson.M{"_id": tag.ID}, tag)
+}
+
+// DeleteTag 删除标签
+func (s *Store) DeleteTag(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.WithTransaction(func(tx *Store) error {
+		ctx := tx.bind(ctx)
+		// 先删除标签与邮件的关联
+		if _, err := tx.c(&domain.MessageTag{}).DeleteMany(ctx, bson.M{"tag_id": id}); err != nil {
+			return err
+		}
+
+		// 再删除标签
+		result, err := tx.c(&domain.Tag{}).DeleteOne(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return fmt.Errorf("tag not found")
+		}
+		return nil
+	})
+}
+
+// AddMessageTag 为邮件添加标签（关联带上邮件所属的邮箱，随邮箱一起删除和过期）
+func (s *Store) AddMessageTag(ctx context.Context, messageID, tagID string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var message domain.Message
+	err := s.findOne(ctx, &message, bson.M{"_id": messageID}, ErrMessageNotFound,
+		options.FindOne().SetProjection(bson.M{"mailbox_id": 1}))
+	if err != nil {
+		return err
+	}
+	expiresAt, err := s.mailboxExpiry(ctx, message.MailboxID)
+	if err != nil {
+		return err
+	}
+
+	messageTag := &domain.MessageTag{
+		MessageID: messageID,
+		TagID:     tagID,
+		CreatedAt: time.Now().UTC(),
+	}
+	return s.insert(ctx, messageTag, bson.E{Key: "mailbox_id", Value: message.MailboxID}, expiresWith(expiresAt))
+}
+
+// RemoveMessageTag 移除邮件标签
+func (s *Store) RemoveMessageTag(ctx context.Context, messageID, tagID string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result, err := s.c(&domain.MessageTag{}).DeleteOne(ctx, bson.M{"message_id": messageID, "tag_id": tagID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("message tag not found")
+	}
+	return nil
+}
+
+// GetMessageTags 获取邮件的所有标签
+func (s *Store) GetMessageTags(ctx context.Context, messageID string) ([]domain.Tag, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	tagIDs, err := s.c(&domain.MessageTag{}).Distinct(ctx, "tag_id", bson.M{"message_id": messageID})
+	if err != nil {
+		return nil, err
+	}
+	tags := []domain.Tag{}
+	if len(tagIDs) == 0 {
+		return tags, nil
+	}
+	err = s.find(ctx, &tags, bson.M{"_id": bson.M{"$in": tagIDs}}, options.Find().SetSort(sortBy("name", 1)))
+	return tags, err
+}
+
+// ListMessagesByTag 列出标签下的所有邮件
+func (s *Store) ListMessagesByTag(ctx context.Context, tagID string) ([]domain.Message, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	messageIDs, err := s.c(&domain.MessageTag{}).Distinct(ctx, "message_id", bson.M{"tag_id": tagID})
+	if err != nil {
+		return nil, err
+	}
+	messages := []domain.Message{}
+	if len(messageIDs) == 0 {
+		return messages, nil
+	}
+	err = s.find(ctx, &messages, bson.M{"_id": bson.M{"$in": messageIDs}},
+		options.Find().SetSort(sortBy("created_at", -1, "seq", -1)))
+	return messages, err
+}
+
+// DeleteMessageTags 删除邮件的所有标签
+func (s *Store) DeleteMessageTags(ctx context.Context, messageID string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	_, err := s.c(&domain.MessageTag{}).DeleteMany(ctx, bson.M{"message_id": messageID})
+	return err
+}
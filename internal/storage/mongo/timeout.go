@@ -0,0 +1,31 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// 存储边界的默认超时（与 SQL 存储一致）：数据库挂起时调用方得到明确的错误，而不是一直阻塞。
+// ctx 已有更早的截止时间或被取消时以 ctx 为准。
+const (
+	// pointTimeout 单条记录的读写
+	pointTimeout = 5 * time.Second
+	// bulkTimeout 列表、搜索、统计、批量写入和级联删除
+	bulkTimeout = 30 * time.Second
+)
+
+// withTimeout 返回加上超时的 ctx（事务内绑定事务会话），调用方必须调用返回的 cancel
+func (s *Store) withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return s.bind(ctx), cancel
+}
+
+// bind 事务内把 ctx 绑定到事务会话（WithTransaction 回调中的操作必须使用绑定后的 ctx）
+func (s *Store) bind(ctx context.Context) context.Context {
+	if s.session != nil {
+		return mongo.NewSessionContext(ctx, s.session)
+	}
+	return ctx
+}
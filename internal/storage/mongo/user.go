@@ -0,0 +1,347 @@
+package mongo
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== User Repository ==========
+
+// CreateUser 创建新用户
+func (s *Store) CreateUser(ctx context.Context, user *domain.User) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	// 生成ID
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+
+	// 如果时间戳为零值，则设置为当前时间（数据迁移时保留原值）
+	now := time.Now().UTC()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+
+	// 邮箱唯一索引拒绝重复注册
+	if err := s.insert(ctx, user); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrEmailExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetUserByID 根据ID获取用户
+func (s *Store) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	return s.getUser(ctx, bson.M{"_id": id})
+}
+
+// GetUserByEmail 根据邮箱获取用户
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return s.getUser(ctx, bson.M{"email": email})
+}
+
+// GetUserByUsername 根据用户名获取用户（不区分大小写）
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	return s.getUser(ctx, bson.M{"username": bson.M{"$regex": "^" + regexp.QuoteMeta(username) + "$", "$options": "i"}})
+}
+
+// getUser 按条件获取一个用户
+func (s *Store) getUser(ctx context.Context, filter bson.M) (*domain.User, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var user domain.User
+	if err := s.findOne(ctx, &user, filter, ErrUserNotFound); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateUser 更新用户信息
+func (s *Store) UpdateUser(ctx context.Context, user *domain.User) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	user.UpdatedAt = time.Now().UTC()
+	return s.upsert(ctx, bson.M{"_id": user.ID}, user)
+}
+
+// UpdateLastLogin 更新用户最后登录时间
+func (s *Store) UpdateLastLogin(ctx context.Context, userID string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	now := time.Now().UTC()
+	_, err := s.c(&domain.User{}).UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{
+		"last_login_at": now,
+		"updated_at":    now,
+	}})
+	return err
+}
+
+// ========== Admin Repository ==========
+
+// ListUsers 列出用户（支持分页和过滤）
+func (s *Store) ListUsers(ctx context.Context, page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	filter := bson.M{}
+
+	// 搜索过滤
+	if search != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(strings.ToLower(search)), "$options": "i"}
+		filter["$or"] = bson.A{bson.M{"email": pattern}, bson.M{"username": pattern}}
+	}
+
+	// 角色、等级和激活状态过滤
+	if role != nil {
+		filter["role"] = *role
+	}
+	if tier != nil {
+		filter["tier"] = *tier
+	}
+	if isActive != nil {
+		filter["is_active"] = *isActive
+	}
+
+	// 获取总数
+	total, err := s.count(ctx, &domain.User{}, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	var users []domain.User
+	err = s.find(ctx, &users, filter, options.Find().
+		SetSort(sortBy("created_at", -1)).
+		SetSkip(int64(max((page-1)*pageSize, 0))).
+		SetLimit(int64(pageSize)))
+	return users, int(total), err
+}
+
+// DeleteUser 删除用户及其所有邮箱
+func (s *Store) DeleteUser(ctx context.Context, userID string) error {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return s.WithTransaction(func(tx *Store) error {
+		ctx := tx.bind(ctx)
+		if err := tx.deleteUserMailboxes(ctx, userID); err != nil {
+			return err
+		}
+		_, err := tx.c(&domain.User{}).DeleteOne(ctx, bson.M{"_id": userID})
+		return err
+	})
+}
+
+// DeleteMailboxesByUserID 删除用户的所有邮箱
+func (s *Store) DeleteMailboxesByUserID(ctx context.Context, userID string) error {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return s.WithTransaction(func(tx *Store) error {
+		return tx.deleteUserMailboxes(tx.bind(ctx), userID)
+	})
+}
+
+// deleteUserMailboxes 删除用户的所有邮箱
+func (s *Store) deleteUserMailboxes(ctx context.Context, userID string) error {
+	ids, err := s.c(&domain.Mailbox{}).Distinct(ctx, "_id", bson.M{"user_id": userID})
+	if err != nil {
+		return err
+	}
+	return s.deleteMailboxes(ctx, stringIDs(ids))
+}
+
+// GetSystemStatistics 获取系统统计信息
+func (s *Store) GetSystemStatistics(ctx context.Context) (*domain.SystemStatistics, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	stats := &domain.SystemStatistics{
+		UsersByTier:       make(map[domain.UserTier]int),
+		UsersByRole:       make(map[domain.UserRole]int),
+		MailboxesByDomain: make(map[string]int),
+		RecentActivity:    make([]domain.ActivityLog, 0),
+	}
+
+	counts := []struct {
+		target *int
+		model  interface{}
+		filter bson.M
+	}{
+		{&stats.TotalUsers, &domain.User{}, bson.M{}},
+		{&stats.ActiveUsers, &domain.User{}, bson.M{"is_active": true}},
+		{&stats.TotalMailboxes, &domain.Mailbox{}, bson.M{}},
+		{&stats.ActiveMailboxes, &domain.Mailbox{}, live(bson.M{}, time.Now())},
+		{&stats.TotalMessages, &domain.Message{}, bson.M{}},
+		{&stats.MessagesToday, &domain.Message{}, bson.M{"created_at": bson.M{"$gte": time.Now().Truncate(24 * time.Hour)}}},
+	}
+	for _, entry := range counts {
+		n, err := s.count(ctx, entry.model, entry.filter)
+		if err != nil {
+			return nil, err
+		}
+		*entry.target = int(n)
+	}
+
+	// 按等级、角色统计用户，按域名统计邮箱
+	tiers, err := s.groupCount(ctx, &domain.User{}, "tier")
+	if err != nil {
+		return nil, err
+	}
+	for tier, n := range tiers {
+		stats.UsersByTier[domain.UserTier(tier)] = n
+	}
+	roles, err := s.groupCount(ctx, &domain.User{}, "role")
+	if err != nil {
+		return nil, err
+	}
+	for role, n := range roles {
+		stats.UsersByRole[domain.UserRole(role)] = n
+	}
+	if stats.MailboxesByDomain, err = s.groupCount(ctx, &domain.Mailbox{}, "domain"); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// groupCount 按字段分组计数
+func (s *Store) groupCount(ctx context.Context, model interface{}, field string) (map[string]int, error) {
+	cursor, err := s.c(model).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Key   string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Key] = row.Count
+	}
+	return counts, nil
+}
+
+// GetDomainStatistics 获取域名统计信息
+func (s *Store) GetDomainStatistics(ctx context.Context, domainName string) (mailboxCount, messageCount int, err error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	ids, err := s.c(&domain.Mailbox{}).Distinct(ctx, "_id", bson.M{"domain": domainName})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(ids) == 0 {
+		return 0, 0, nil
+	}
+	messages, err := s.count(ctx, &domain.Message{}, bson.M{"mailbox_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(ids), int(messages), nil
+}
+
+// ========== API Key Repository ==========
+
+// SaveAPIKey 保存API Key
+func (s *Store) SaveAPIKey(ctx context.Context, apiKey *domain.APIKey) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	if apiKey.ID == "" {
+		apiKey.ID = uuid.New().String()
+	}
+
+	// 保留已有的创建时间（数据迁移）
+	if apiKey.CreatedAt.IsZero() {
+		apiKey.CreatedAt = time.Now().UTC()
+	}
+
+	return s.insert(ctx, apiKey)
+}
+
+// GetAPIKey 根据ID获取API Key
+func (s *Store) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	return s.getAPIKey(ctx, bson.M{"_id": id})
+}
+
+// GetAPIKeyByKey 根据Key字符串获取API Key
+func (s *Store) GetAPIKeyByKey(ctx context.Context, key string) (*domain.APIKey, error) {
+	return s.getAPIKey(ctx, bson.M{"key_hash": key})
+}
+
+// getAPIKey 按条件获取一个 API Key
+func (s *Store) getAPIKey(ctx context.Context, filter bson.M) (*domain.APIKey, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var apiKey domain.APIKey
+	if err := s.findOne(ctx, &apiKey, filter, ErrUserNotFound); err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+// ListAPIKeysByUserID 列出用户的所有API Key
+func (s *Store) ListAPIKeysByUserID(ctx context.Context, userID string) ([]*domain.APIKey, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var apiKeys []*domain.APIKey
+	err := s.find(ctx, &apiKeys, bson.M{"user_id": userID}, options.Find().SetSort(sortBy("created_at", -1)))
+	return apiKeys, err
+}
+
+// DeleteAPIKey 删除API Key
+func (s *Store) DeleteAPIKey(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result, err := s.c(&domain.APIKey{}).DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateAPIKeyLastUsed 更新API Key最后使用时间
+func (s *Store) UpdateAPIKeyLastUsed(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result, err := s.c(&domain.APIKey{}).UpdateOne(ctx, bson.M{"_id": id},
+		bson.M{"$set": bson.M{"last_used_at": time.Now().UTC()}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// GetUserByAPIKey 根据API Key获取用户
+func (s *Store) GetUserByAPIKey(ctx context.Context, apiKey string) (*domain.User, error) {
+	key, err := s.getAPIKey(ctx, bson.M{"key_hash": apiKey, "is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetUserByID(ctx, key.UserID)
+}
@@ -0,0 +1,92 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== User Session Repository ==========
+
+// CreateUserSession 创建登录会话
+func (s *Store) CreateUserSession(ctx context.Context, session *domain.UserSession) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.insert(ctx, session)
+}
+
+// GetUserSession 按ID获取登录会话
+func (s *Store) GetUserSession(ctx context.Context, id string) (*domain.UserSession, error) {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var session domain.UserSession
+	if err := s.findOne(ctx, &session, bson.M{"_id": id}, storage.ErrUserSessionNotFound); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RotateUserSession 以刷新令牌 jti 做条件更新，并发刷新时只有一个成功
+func (s *Store) RotateUserSession(ctx context.Context, session *domain.UserSession, previousRefreshID string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result, err := s.c(session).UpdateOne(ctx,
+		bson.M{"_id": session.ID, "refresh_token_id": previousRefreshID},
+		bson.M{"$set": bson.M{
+			"refresh_token_id":  session.RefreshTokenID,
+			"access_token_id":   session.AccessTokenID,
+			"access_expires_at": session.AccessExpiresAt,
+			"ip":                session.IP,
+			"user_agent":        session.UserAgent,
+			"last_used_at":      session.LastUsedAt,
+			"expires_at":        session.ExpiresAt,
+		}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+	count, err := s.count(ctx, session, bson.M{"_id": session.ID})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrUserSessionNotFound
+	}
+	return storage.ErrUserSessionRotated
+}
+
+// ListUserSessions 列出用户的会话（按最近使用时间倒序）
+func (s *Store) ListUserSessions(ctx context.Context, userID string) ([]*domain.UserSession, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var sessions []*domain.UserSession
+	if err := s.find(ctx, &sessions, bson.M{"user_id": userID}, options.Find().SetSort(sortBy("last_used_at", -1))); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DeleteUserSession 删除登录会话
+func (s *Store) DeleteUserSession(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return s.deleteOneContext(ctx, &domain.UserSession{}, bson.M{"_id": id}, storage.ErrUserSessionNotFound)
+}
+
+// DeleteExpiredUserSessions 删除已过期的登录会话
+func (s *Store) DeleteExpiredUserSessions(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	result, err := s.c(&domain.UserSession{}).DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
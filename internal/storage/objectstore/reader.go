@@ -0,0 +1,70 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var errNegativeOffset = errors.New("objectstore: negative offset")
+
+// objectReader 可定位的对象读取器
+//
+// 首次读取时才发起下载；Seek 只记录偏移量，下一次读取从新位置发起 Range 请求，
+// 下载接口按 Range 返回大附件时不需要把整个对象读入内存。
+type objectReader struct {
+	client *client
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser // 当前下载流，未开始或定位后为空
+}
+
+func newObjectReader(c *client, key string, size int64) *objectReader {
+	return &objectReader{client: c, key: key, size: size}
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, _, err := r.client.getFrom(context.Background(), r.key, r.offset)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *objectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	if offset != r.offset {
+		r.closeBody()
+		r.offset = offset
+	}
+	return offset, nil
+}
+
+func (r *objectReader) Close() error {
+	r.closeBody()
+	return nil
+}
+
+func (r *objectReader) closeBody() {
+	if r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+}
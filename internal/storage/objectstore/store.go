@@ -0,0 +1,342 @@
+// Package objectstore 将邮件内容和附件保存到 S3 兼容的对象存储（AWS S3、MinIO）
+//
+// 与文件系统存储提供相同的方法，多副本部署时各实例共享同一存储桶，不需要共享卷。
+// 对象布局与本地目录一致：{prefix}mails/{mailboxID}/{messageID}/raw.eml、metadata.json
+// 和 attachments/{attachmentID}；附件不做跨邮件去重。
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/filesystem"
+)
+
+var (
+	ErrMissingBucket   = errors.New("object storage bucket required")
+	ErrMissingEndpoint = errors.New("object storage endpoint required")
+)
+
+// requestTimeout 单次对象操作的超时（接口不带 ctx，与文件系统存储保持一致）
+const requestTimeout = 60 * time.Second
+
+// Store 对象存储实现
+type Store struct {
+	client *client
+	prefix string // 对象键前缀（以 / 结尾或为空）
+}
+
+// NewStore 根据配置创建对象存储，启动时检查存储桶是否可访问
+func NewStore(cfg config.S3Config) (*Store, error) {
+	if cfg.Bucket == "" {
+		return nil, ErrMissingBucket
+	}
+	if cfg.Endpoint == "" {
+		return nil, ErrMissingEndpoint
+	}
+	endpoint := cfg.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object storage endpoint: %w", err)
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	s := &Store{
+		client: &client{
+			endpoint:  u,
+			bucket:    cfg.Bucket,
+			region:    region,
+			accessKey: cfg.AccessKey,
+			secretKey: cfg.SecretKey,
+			pathStyle: cfg.PathStyle,
+			http:      &http.Client{},
+			now:       time.Now,
+		},
+		prefix: prefix,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if _, _, err := s.client.list(ctx, s.prefix+"status/", ""); err != nil {
+		return nil, fmt.Errorf("object storage unavailable: %w", err)
+	}
+	return s, nil
+}
+
+// ========== 邮件存储 ==========
+
+// SaveMessageRaw 保存邮件原始内容
+func (s *Store) SaveMessageRaw(mailboxID, messageID string, rawContent []byte) (string, error) {
+	key := s.messageKey(mailboxID, messageID, "raw.eml")
+	if err := s.put(key, bytes.NewReader(rawContent), int64(len(rawContent)), "message/rfc822"); err != nil {
+		return "", fmt.Errorf("failed to write raw message: %w", err)
+	}
+	return key, nil
+}
+
+// SaveMessageRawFile 上传已落盘的原始邮件临时文件
+func (s *Store) SaveMessageRawFile(mailboxID, messageID, srcPath string) (string, error) {
+	file, err := os.Open(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open raw message: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to open raw message: %w", err)
+	}
+
+	key := s.messageKey(mailboxID, messageID, "raw.eml")
+	if err := s.put(key, file, info.Size(), "message/rfc822"); err != nil {
+		return "", fmt.Errorf("failed to write raw message: %w", err)
+	}
+	return key, nil
+}
+
+// GetMessageRaw 读取邮件原始内容
+func (s *Store) GetMessageRaw(mailboxID, messageID string) ([]byte, error) {
+	data, err := s.read(s.messageKey(mailboxID, messageID, "raw.eml"))
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil, fmt.Errorf("message raw content not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raw message: %w", err)
+	}
+	return data, nil
+}
+
+// SaveMessageMetadata 保存邮件元数据（与文件系统存储相同的 JSON 格式）
+func (s *Store) SaveMessageMetadata(mailboxID, messageID string, message *domain.Message) (string, error) {
+	data, err := filesystem.MarshalMetadata(message)
+	if err != nil {
+		return "", err
+	}
+	key := s.messageKey(mailboxID, messageID, "metadata.json")
+	if err := s.put(key, bytes.NewReader(data), int64(len(data)), "application/json"); err != nil {
+		return "", fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return key, nil
+}
+
+// GetMessageMetadata 读取邮件元数据
+func (s *Store) GetMessageMetadata(mailboxID, messageID string) (*domain.Message, error) {
+	data, err := s.read(s.messageKey(mailboxID, messageID, "metadata.json"))
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil, fmt.Errorf("message metadata not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	var message domain.Message
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+	return &message, nil
+}
+
+// ========== 附件存储 ==========
+
+// SaveAttachment 上传邮件附件，返回对象键
+//
+// 内存中没有内容的大附件通过 Open 流式上传，需要已知大小。
+func (s *Store) SaveAttachment(mailboxID, messageID, attachmentID string, attachment *domain.Attachment) (string, error) {
+	content, err := attachment.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to read attachment: %w", err)
+	}
+	defer content.Close()
+
+	size := attachment.Size
+	if attachment.Content != nil {
+		size = int64(len(attachment.Content))
+	}
+	key := s.messageKey(mailboxID, messageID, "attachments/"+attachmentID)
+	if err := s.put(key, content, size, "application/octet-stream"); err != nil {
+		return "", fmt.Errorf("failed to write attachment: %w", err)
+	}
+	return key, nil
+}
+
+// GetAttachment 读取附件元数据；小附件直接读入内存，大附件通过 Open 按需下载（支持定位）
+func (s *Store) GetAttachment(mailboxID, messageID, attachmentID string) (*domain.Attachment, error) {
+	metadata, err := s.GetMessageMetadata(mailboxID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message metadata: %w", err)
+	}
+	var attachment *domain.Attachment
+	for _, att := range metadata.Attachments {
+		if att.ID == attachmentID {
+			attachment = att
+			break
+		}
+	}
+	if attachment == nil {
+		return nil, fmt.Errorf("attachment not found in metadata")
+	}
+
+	key := s.messageKey(mailboxID, messageID, "attachments/"+attachmentID)
+	if attachment.Size <= filesystem.InlineAttachmentBytes {
+		content, err := s.read(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment: %w", err)
+		}
+		attachment.Content = content
+	}
+	size := attachment.Size
+	attachment.SetOpener(func() (io.ReadCloser, error) {
+		return newObjectReader(s.client, key, size), nil
+	})
+	return attachment, nil
+}
+
+// ========== 删除和清理 ==========
+
+// DeleteMessage 删除邮件的全部对象
+func (s *Store) DeleteMessage(mailboxID, messageID string) error {
+	return s.deletePrefix(s.messageKey(mailboxID, messageID, ""))
+}
+
+// DeleteMailbox 删除邮箱的全部对象
+func (s *Store) DeleteMailbox(mailboxID string) error {
+	return s.deletePrefix(s.prefix + "mails/" + mailboxID + "/")
+}
+
+// ListMailboxIDs 列出存储中有内容的邮箱ID（用于对账孤儿内容）
+func (s *Store) ListMailboxIDs() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	root := s.prefix + "mails/"
+	_, prefixes, err := s.client.list(ctx, root, "/")
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		ids = append(ids, strings.TrimSuffix(strings.TrimPrefix(p, root), "/"))
+	}
+	return ids, nil
+}
+
+// CleanupExpired 删除超过保留天数的邮件（按邮件最后写入的对象时间判断），返回删除的邮件数
+func (s *Store) CleanupExpired(retentionDays int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	objects, _, err := s.client.list(ctx, s.prefix+"mails/", "")
+	if err != nil {
+		return 0, err
+	}
+
+	// 按邮件分组：{prefix}mails/{mailboxID}/{messageID}/...
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	latest := make(map[string]time.Time)
+	keys := make(map[string][]string)
+	for _, obj := range objects {
+		parts := strings.SplitN(strings.TrimPrefix(obj.Key, s.prefix+"mails/"), "/", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		message := parts[0] + "/" + parts[1]
+		if obj.LastModified.After(latest[message]) {
+			latest[message] = obj.LastModified
+		}
+		keys[message] = append(keys[message], obj.Key)
+	}
+
+	count := 0
+	for message, modified := range latest {
+		if modified.After(cutoff) {
+			continue
+		}
+		for _, key := range keys[message] {
+			if err := s.client.delete(ctx, key); err != nil {
+				return count, err
+			}
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ========== 状态历史 ==========
+
+// SaveUptimeHistory 保存运行时间历史
+func (s *Store) SaveUptimeHistory(data []byte) error {
+	if err := s.put(s.prefix+"status/uptime.json", bytes.NewReader(data), int64(len(data)), "application/json"); err != nil {
+		return fmt.Errorf("failed to write uptime history: %w", err)
+	}
+	return nil
+}
+
+// LoadUptimeHistory 读取运行时间历史，不存在时返回空
+func (s *Store) LoadUptimeHistory() ([]byte, error) {
+	data, err := s.read(s.prefix + "status/uptime.json")
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uptime history: %w", err)
+	}
+	return data, nil
+}
+
+// ========== 辅助方法 ==========
+
+// messageKey 邮件对象键：{prefix}mails/{mailboxID}/{messageID}/{name}
+func (s *Store) messageKey(mailboxID, messageID, name string) string {
+	return s.prefix + "mails/" + mailboxID + "/" + messageID + "/" + name
+}
+
+func (s *Store) put(key string, body io.Reader, size int64, contentType string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return s.client.put(ctx, key, body, size, contentType)
+}
+
+func (s *Store) read(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	body, _, err := s.client.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// deletePrefix 删除前缀下的全部对象
+func (s *Store) deletePrefix(prefix string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	objects, _, err := s.client.list(ctx, prefix, "")
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := s.client.delete(ctx, obj.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
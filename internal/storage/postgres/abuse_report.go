@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Abuse Report Repository ==========
+
+// SaveAbuseReport 保存滥用举报（覆盖已有记录）
+func (s *Store) SaveAbuseReport(ctx context.Context, report *domain.AbuseReport) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(report).Error
+}
+
+// GetAbuseReport 获取滥用举报
+func (s *Store) GetAbuseReport(ctx context.Context, id string) (*domain.AbuseReport, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var report domain.AbuseReport
+	if err := db.Where("id = ?", id).First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrAbuseReportNotFound
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ListAbuseReports 按过滤条件列出举报（按创建时间倒序）
+func (s *Store) ListAbuseReports(ctx context.Context, filter domain.AbuseReportFilter) ([]*domain.AbuseReport, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	query := db.Model(&domain.AbuseReport{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.MailboxID != "" {
+		query = query.Where("mailbox_id = ?", filter.MailboxID)
+	}
+	if filter.Priority {
+		query = query.Where("priority = ?", true)
+	}
+
+	var reports []*domain.AbuseReport
+	if err := query.Order("created_at DESC, id DESC").Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
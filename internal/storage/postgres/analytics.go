@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Analytics Repository ==========
+
+// MergeAnalyticsBuckets 合并统计计数
+//
+// 计数类指标累加，峰值类指标取最大值；用 upsert 完成，多个实例同时写入同一时间桶时不会丢失计数。
+func (s *Store) MergeAnalyticsBuckets(ctx context.Context, buckets []domain.AnalyticsBucket) error {
+	var sums, peaks []domain.AnalyticsBucket
+	for _, bucket := range buckets {
+		bucket.Bucket = bucket.Bucket.UTC()
+		if domain.IsPeakAnalyticsMetric(bucket.Metric) {
+			peaks = append(peaks, bucket)
+		} else {
+			sums = append(sums, bucket)
+		}
+	}
+
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, group := range []struct {
+			buckets []domain.AnalyticsBucket
+			peak    bool
+		}{{sums, false}, {peaks, true}} {
+			if len(group.buckets) == 0 {
+				continue
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "metric"}, {Name: "dimension"}, {Name: "bucket"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"value": s.analyticsMergeExpr(group.peak)}),
+			}).CreateInBatches(group.buckets, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// analyticsMergeExpr 时间桶已存在时更新 value 的 SQL 表达式
+func (s *Store) analyticsMergeExpr(peak bool) clause.Expr {
+	existing, incoming := "analytics_buckets.value", "excluded.value"
+	greatest := "GREATEST"
+	switch s.db.Dialector.Name() {
+	case "mysql":
+		incoming = "VALUES(value)"
+	case "sqlite":
+		greatest = "MAX" // SQLite 的多参数 MAX 即标量最大值
+	}
+	if peak {
+		return gorm.Expr(greatest + "(" + existing + ", " + incoming + ")")
+	}
+	return gorm.Expr(existing + " + " + incoming)
+}
+
+// ListAnalyticsBuckets 列出指标在 [since, until) 内的时间桶
+func (s *Store) ListAnalyticsBuckets(ctx context.Context, metric string, since, until time.Time) ([]domain.AnalyticsBucket, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var buckets []domain.AnalyticsBucket
+	err := db.Where("metric = ? AND bucket >= ? AND bucket < ?", metric, since.UTC(), until.UTC()).
+		Order("bucket ASC, dimension ASC").
+		Find(&buckets).Error
+	for i := range buckets {
+		buckets[i].Bucket = buckets[i].Bucket.UTC()
+	}
+	return buckets, err
+}
+
+// DeleteAnalyticsBucketsBefore 删除早于 before 的时间桶
+func (s *Store) DeleteAnalyticsBucketsBefore(ctx context.Context, before time.Time) (int64, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	result := db.Where("bucket < ?", before.UTC()).Delete(&domain.AnalyticsBucket{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Audit Log Repository ==========
+
+// CreateAuditLog 追加审计记录
+func (s *Store) CreateAuditLog(ctx context.Context, log *domain.AuditLog) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	return db.Create(log).Error
+}
+
+// ListAuditLogs 按过滤条件分页列出审计记录（按时间倒序）
+func (s *Store) ListAuditLogs(ctx context.Context, filter domain.AuditLogFilter) ([]*domain.AuditLog, int64, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	query := db.Model(&domain.AuditLog{})
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var logs []*domain.AuditLog
+	if err := query.Order("created_at DESC, id DESC").Offset(filter.Offset).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// newTestStore 连接 TEMPMAIL_TEST_POSTGRES_DSN 指定的测试库，未设置时跳过
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	dsn := os.Getenv("TEMPMAIL_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEMPMAIL_TEST_POSTGRES_DSN not set")
+	}
+	store, err := NewStore(dsn)
+	require.NoError(t, err)
+	require.NoError(t, store.migrate())
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStore_DeleteMailboxRemovesDependents(t *testing.T) {
+	store := newTestStore(t)
+
+	suffix := uuid.NewString()[:8]
+	mailbox := &domain.Mailbox{
+		ID: uuid.NewString(), Address: "del-" + suffix + "@corp.example", LocalPart: "del-" + suffix,
+		Domain: "corp.example", Token: "tok-" + suffix, CreatedAt: time.Now(),
+	}
+	require.NoError(t, store.SaveMailbox(t.Context(), mailbox))
+
+	msg := &domain.Message{ID: uuid.NewString(), MailboxID: mailbox.ID, Subject: "hi", ReceivedAt: time.Now(), CreatedAt: time.Now()}
+	require.NoError(t, store.SaveMessage(t.Context(), msg))
+	tag := &domain.Tag{ID: uuid.NewString(), UserID: uuid.NewString(), Name: "t-" + suffix}
+	require.NoError(t, store.CreateTag(t.Context(), tag))
+	require.NoError(t, store.AddMessageTag(t.Context(), msg.ID, tag.ID))
+	alias := &domain.MailboxAlias{ID: uuid.NewString(), MailboxID: mailbox.ID, Address: "alias-" + suffix + "@corp.example"}
+	require.NoError(t, store.SaveAlias(t.Context(), alias))
+
+	webhook := &domain.Webhook{ID: uuid.NewString(), UserID: tag.UserID, URL: "https://hooks.example.com", IsActive: true}
+	require.NoError(t, store.CreateWebhook(t.Context(), webhook))
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, store.RecordDelivery(t.Context(), &domain.WebhookDelivery{
+		ID: uuid.NewString(), WebhookID: webhook.ID, MailboxID: mailbox.ID, NextRetry: &past,
+	}))
+
+	require.NoError(t, store.DeleteMailbox(t.Context(), mailbox.ID))
+
+	t.Run("邮箱、邮件、标签关联和别名全部删除", func(t *testing.T) {
+		_, err := store.GetMailbox(t.Context(), mailbox.ID)
+		assert.ErrorIs(t, err, storage.ErrMailboxNotFound)
+
+		var count int64
+		require.NoError(t, store.db.Model(&domain.Message{}).Where("mailbox_id = ?", mailbox.ID).Count(&count).Error)
+		assert.Zero(t, count)
+		require.NoError(t, store.db.Model(&domain.MessageTag{}).Where("message_id = ?", msg.ID).Count(&count).Error)
+		assert.Zero(t, count)
+		require.NoError(t, store.db.Model(&domain.MailboxAlias{}).Where("mailbox_id = ?", mailbox.ID).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+
+	t.Run("取消待重试投递", func(t *testing.T) {
+		cancelled, err := store.CancelPendingDeliveries(t.Context(), mailbox.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, cancelled)
+
+		pending, err := store.GetPendingDeliveries(t.Context(), 100)
+		require.NoError(t, err)
+		for _, delivery := range pending {
+			assert.NotEqual(t, mailbox.ID, delivery.MailboxID)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Distribution List Repository ==========
+
+// SaveDistributionList 保存分发列表（地址唯一）
+func (s *Store) SaveDistributionList(ctx context.Context, list *domain.DistributionList) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing domain.DistributionList
+		err := tx.Where("address = ? AND id != ?", list.Address, list.ID).First(&existing).Error
+		if err == nil {
+			return storage.ErrDistributionListExists
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Save(list).Error
+	})
+}
+
+// GetDistributionList 获取分发列表
+func (s *Store) GetDistributionList(ctx context.Context, id string) (*domain.DistributionList, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var list domain.DistributionList
+	if err := db.Where("id = ?", id).First(&list).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrDistributionListNotFound
+		}
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetDistributionListByAddress 根据地址获取分发列表
+func (s *Store) GetDistributionListByAddress(ctx context.Context, address string) (*domain.DistributionList, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var list domain.DistributionList
+	if err := db.Where("address = ?", address).First(&list).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrDistributionListNotFound
+		}
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ListDistributionListsByUserID 列出用户创建的分发列表
+func (s *Store) ListDistributionListsByUserID(ctx context.Context, userID string) ([]*domain.DistributionList, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var lists []*domain.DistributionList
+	err := db.Where("user_id = ?", userID).Order("created_at").Find(&lists).Error
+	return lists, err
+}
+
+// ListDistributionListsByOrgID 列出组织的分发列表
+func (s *Store) ListDistributionListsByOrgID(ctx context.Context, orgID string) ([]*domain.DistributionList, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var lists []*domain.DistributionList
+	err := db.Where("org_id = ?", orgID).Order("created_at").Find(&lists).Error
+	return lists, err
+}
+
+// DeleteDistributionList 删除分发列表及其投递报告
+func (s *Store) DeleteDistributionList(ctx context.Context, id string) error {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ?", id).Delete(&domain.DistributionListDelivery{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&domain.DistributionList{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return storage.ErrDistributionListNotFound
+		}
+		return nil
+	})
+}
+
+// SaveDistributionListDelivery 记录分发列表投递报告
+func (s *Store) SaveDistributionListDelivery(ctx context.Context, delivery *domain.DistributionListDelivery) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Create(delivery).Error
+}
+
+// ListDistributionListDeliveries 列出分发列表最近的投递报告（新的在前）
+func (s *Store) ListDistributionListDeliveries(ctx context.Context, listID string, limit int) ([]*domain.DistributionListDelivery, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var deliveries []*domain.DistributionListDelivery
+	query := db.Where("list_id = ?", listID).Order("created_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&deliveries).Error
+	return deliveries, err
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+
+	"gorm.io/gorm/clause"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Domain Whitelist Repository ==========
+
+// AddDomainWhitelistEntry 添加白名单条目（依赖 (domain_id, local_part) 唯一索引判断重复）
+func (s *Store) AddDomainWhitelistEntry(ctx context.Context, entry *domain.DomainWhitelistEntry) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrWhitelistEntryExists
+	}
+	return nil
+}
+
+// ListDomainWhitelist 列出域名的白名单（按前缀排序）
+func (s *Store) ListDomainWhitelist(ctx context.Context, domainID string) ([]*domain.DomainWhitelistEntry, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var entries []*domain.DomainWhitelistEntry
+	if err := db.Where("domain_id = ?", domainID).Order("local_part").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DeleteDomainWhitelistEntry 删除域名下的白名单条目
+func (s *Store) DeleteDomainWhitelistEntry(ctx context.Context, domainID, id string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result := db.Where("id = ? AND domain_id = ?", id, domainID).Delete(&domain.DomainWhitelistEntry{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrWhitelistEntryNotFound
+	}
+	return nil
+}
+
+// IsLocalPartWhitelisted 前缀是否在域名白名单中
+func (s *Store) IsLocalPartWhitelisted(ctx context.Context, domainID, localPart string) (bool, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var count int64
+	if err := db.Model(&domain.DomainWhitelistEntry{}).
+		Where("domain_id = ? AND local_part = ?", domainID, localPart).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Forward Repository ==========
+
+// SaveMailboxForward 保存转发地址（新建或更新）
+func (s *Store) SaveMailboxForward(ctx context.Context, forward *domain.MailboxForward) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(forward).Error
+}
+
+// GetMailboxForward 获取邮箱下的转发地址
+func (s *Store) GetMailboxForward(ctx context.Context, mailboxID, id string) (*domain.MailboxForward, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var forward domain.MailboxForward
+	if err := db.Where("id = ? AND mailbox_id = ?", id, mailboxID).Take(&forward).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrForwardNotFound
+		}
+		return nil, err
+	}
+	return &forward, nil
+}
+
+// ListMailboxForwards 列出邮箱的转发地址（按创建时间排序）
+func (s *Store) ListMailboxForwards(ctx context.Context, mailboxID string) ([]*domain.MailboxForward, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var forwards []*domain.MailboxForward
+	if err := db.Where("mailbox_id = ?", mailboxID).Order("created_at, id").Find(&forwards).Error; err != nil {
+		return nil, err
+	}
+	return forwards, nil
+}
+
+// DeleteMailboxForward 删除邮箱下的转发地址及其投递记录
+func (s *Store) DeleteMailboxForward(ctx context.Context, mailboxID, id string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND mailbox_id = ?", id, mailboxID).Delete(&domain.MailboxForward{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return storage.ErrForwardNotFound
+		}
+		return tx.Where("forward_id = ?", id).Delete(&domain.ForwardDelivery{}).Error
+	})
+}
+
+// SaveForwardDelivery 保存投递记录（新建或更新）
+func (s *Store) SaveForwardDelivery(ctx context.Context, delivery *domain.ForwardDelivery) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(delivery).Error
+}
+
+// ListPendingForwardDeliveries 列出到期待投递的记录（按 NextRetry 排序）
+func (s *Store) ListPendingForwardDeliveries(ctx context.Context, now time.Time, limit int) ([]*domain.ForwardDelivery, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var deliveries []*domain.ForwardDelivery
+	query := db.Where("success = ? AND next_retry IS NOT NULL AND next_retry <= ?", false, now).Order("next_retry")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
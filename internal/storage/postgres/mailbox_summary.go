@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"tempmail/backend/internal/domain"
+)
+
+// mailboxSummaryRow 邮箱摘要查询结果（最近邮件的列在邮箱没有邮件时为 NULL）
+type mailboxSummaryRow struct {
+	domain.Mailbox
+	SummaryTotal   int
+	SummaryUnread  int
+	SummaryBytes   int64
+	LastID         *string
+	LastSubject    *string
+	LastFrom       *string
+	LastReceivedAt *time.Time
+}
+
+// ListMailboxSummariesByUserID 返回指定用户的邮箱摘要
+func (s *Store) ListMailboxSummariesByUserID(ctx context.Context, userID string) ([]domain.MailboxSummary, error) {
+	return s.listMailboxSummaries(ctx, "user_id", userID)
+}
+
+// ListMailboxSummariesByOrgID 返回指定组织的邮箱摘要
+func (s *Store) ListMailboxSummariesByOrgID(ctx context.Context, orgID string) ([]domain.MailboxSummary, error) {
+	return s.listMailboxSummaries(ctx, "org_id", orgID)
+}
+
+// listMailboxSummaries 用一条查询取出邮箱、按邮件表实时统计的数量和最近一封邮件：
+// 数量来自按邮箱分组的子查询（只统计这些邮箱的邮件），最近邮件按 (mailbox_id, seq) 索引取第一条。
+// 隔离区邮件不计入数量和预览，但计入占用字节。
+func (s *Store) listMailboxSummaries(ctx context.Context, column, value string) ([]domain.MailboxSummary, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	owned := db.Model(&domain.Mailbox{}).Select("id").Where(column+" = ?", value)
+	stats := db.Model(&domain.Message{}).
+		Select("mailbox_id, "+
+			"COUNT(CASE WHEN quarantined THEN NULL ELSE 1 END) AS total, "+
+			"COALESCE(SUM(CASE WHEN quarantined OR is_read THEN 0 ELSE 1 END), 0) AS unread, "+
+			"COALESCE(SUM(size), 0) AS bytes").
+		Where("mailbox_id IN (?)", owned).
+		Group("mailbox_id")
+
+	var rows []mailboxSummaryRow
+	err := db.Table("mailboxes").
+		Select("mailboxes.*, "+
+			"COALESCE(stats.total, 0) AS summary_total, COALESCE(stats.unread, 0) AS summary_unread, COALESCE(stats.bytes, 0) AS summary_bytes, "+
+			"latest.id AS last_id, latest.subject AS last_subject, ? AS last_from, latest.received_at AS last_received_at",
+			clause.Column{Table: "latest", Name: "from"}).
+		Joins("LEFT JOIN (?) AS stats ON stats.mailbox_id = mailboxes.id", stats).
+		Joins("LEFT JOIN messages AS latest ON latest.id = ("+
+			"SELECT m.id FROM messages AS m WHERE m.mailbox_id = mailboxes.id AND NOT m.quarantined "+
+			"ORDER BY m.seq DESC, m.received_at DESC, m.id DESC LIMIT 1)").
+		Where("mailboxes."+column+" = ? AND (mailboxes.expires_at IS NULL OR mailboxes.expires_at > ?)", value, time.Now()).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mailbox summaries: %w", err)
+	}
+
+	summaries := make([]domain.MailboxSummary, 0, len(rows))
+	for _, row := range rows {
+		summary := domain.MailboxSummary{Mailbox: row.Mailbox, StorageBytes: row.SummaryBytes}
+		summary.TotalCount = row.SummaryTotal
+		summary.Unread = row.SummaryUnread
+		if row.LastID != nil {
+			preview := &domain.MessagePreview{ID: *row.LastID}
+			if row.LastSubject != nil {
+				preview.Subject = *row.LastSubject
+			}
+			if row.LastFrom != nil {
+				preview.From = *row.LastFrom
+			}
+			if row.LastReceivedAt != nil {
+				preview.ReceivedAt = *row.LastReceivedAt
+			}
+			summary.LastMessage = preview
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
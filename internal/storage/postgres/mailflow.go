@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Mail Flow Repository ==========
+
+// MergeMailFlowCounters 累加收信流量计数（upsert，多个实例同时写入同一分钟不会丢失计数）
+func (s *Store) MergeMailFlowCounters(ctx context.Context, counters []domain.MailFlowCounter) error {
+	if len(counters) == 0 {
+		return nil
+	}
+	for i := range counters {
+		counters[i].Bucket = counters[i].Bucket.UTC()
+	}
+
+	incoming := "excluded.count"
+	if s.db.Dialector.Name() == "mysql" {
+		incoming = "VALUES(count)"
+	}
+
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "dimension"}, {Name: "bucket"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("mail_flow_counters.count + " + incoming)}),
+	}).CreateInBatches(counters, 500).Error
+}
+
+// ListMailFlowCounters 列出 [since, until) 内的收信流量计数
+func (s *Store) ListMailFlowCounters(ctx context.Context, since, until time.Time) ([]domain.MailFlowCounter, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var counters []domain.MailFlowCounter
+	err := db.Where("bucket >= ? AND bucket < ?", since.UTC(), until.UTC()).
+		Order("bucket ASC, kind ASC, dimension ASC").
+		Find(&counters).Error
+	for i := range counters {
+		counters[i].Bucket = counters[i].Bucket.UTC()
+	}
+	return counters, err
+}
+
+// DeleteMailFlowCountersBefore 删除早于 before 的收信流量计数
+func (s *Store) DeleteMailFlowCountersBefore(ctx context.Context, before time.Time) (int64, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	result := db.Where("bucket < ?", before.UTC()).Delete(&domain.MailFlowCounter{})
+	return result.RowsAffected, result.Error
+}
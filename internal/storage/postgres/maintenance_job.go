@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Maintenance Job Repository ==========
+
+// CreateMaintenanceJob 创建维护任务
+//
+// 同类型已有排队或运行中的任务时拒绝；并发创建由 active_type 唯一索引兜底，插入失败时再检查一次占用。
+func (s *Store) CreateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	job.SyncActiveType()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if job.ActiveType != nil {
+			active, err := activeMaintenanceJob(tx, job)
+			if err != nil {
+				return err
+			}
+			if active {
+				return storage.ErrMaintenanceJobActive
+			}
+		}
+		return tx.Create(job).Error
+	})
+	if err != nil && !errors.Is(err, storage.ErrMaintenanceJobActive) && job.ActiveType != nil {
+		if active, checkErr := activeMaintenanceJob(db, job); checkErr == nil && active {
+			return storage.ErrMaintenanceJobActive
+		}
+	}
+	return err
+}
+
+// activeMaintenanceJob 是否有其他同类型的任务排队或运行中
+func activeMaintenanceJob(db *gorm.DB, job *domain.MaintenanceJob) (bool, error) {
+	var count int64
+	err := db.Model(&domain.MaintenanceJob{}).
+		Where("active_type = ? AND id <> ?", string(job.Type), job.ID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetMaintenanceJob 获取维护任务
+func (s *Store) GetMaintenanceJob(ctx context.Context, id string) (*domain.MaintenanceJob, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var job domain.MaintenanceJob
+	if err := db.Where("id = ?", id).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrMaintenanceJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListMaintenanceJobs 列出维护任务（按创建时间倒序）
+func (s *Store) ListMaintenanceJobs(ctx context.Context) ([]*domain.MaintenanceJob, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var jobs []*domain.MaintenanceJob
+	if err := db.Order("created_at DESC, id DESC").Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// UpdateMaintenanceJob 保存维护任务状态和进度，任务结束后清空 active_type 释放占用
+func (s *Store) UpdateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	job.SyncActiveType()
+	result := db.Model(&domain.MaintenanceJob{}).Where("id = ?", job.ID).Select("*").Omit("id", "created_at").Updates(job)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrMaintenanceJobNotFound
+	}
+	return nil
+}
+
+// ========== Maintenance Scan ==========
+
+// CountMessages 统计全部邮件数
+func (s *Store) CountMessages(ctx context.Context) (int64, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var count int64
+	err := db.Model(&domain.Message{}).Count(&count).Error
+	return count, err
+}
+
+// ListMessagesAfter 按 ID 升序列出 ID 大于 afterID 的邮件（只取元数据，不加载附件）
+func (s *Store) ListMessagesAfter(ctx context.Context, afterID string, limit int) ([]domain.Message, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var messages []domain.Message
+	err := db.Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// SetMessageDerivedText 标记邮件已有由 HTML 生成的纯文本（正文本身不存数据库）
+func (s *Store) SetMessageDerivedText(ctx context.Context, mailboxID, messageID, text string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result := db.Model(&domain.Message{}).Where("id = ? AND mailbox_id = ?", messageID, mailboxID).Updates(map[string]interface{}{
+		"has_text":               true,
+		"text_derived_from_html": true,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// CountMailboxes 统计全部邮箱数
+func (s *Store) CountMailboxes(ctx context.Context) (int64, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var count int64
+	err := db.Model(&domain.Mailbox{}).Count(&count).Error
+	return count, err
+}
+
+// ListMailboxIDsAfter 按 ID 升序列出 ID 大于 afterID 的邮箱 ID
+func (s *Store) ListMailboxIDsAfter(ctx context.Context, afterID string, limit int) ([]string, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var ids []string
+	err := db.Model(&domain.Mailbox{}).Where("id > ?", afterID).Order("id ASC").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// RecountMailbox 按邮件重新统计邮箱的总数和未读数
+//
+// 统计和写回在同一条 UPDATE 中完成，不会覆盖期间新到邮件的增量；统计没有变化时不写。
+func (s *Store) RecountMailbox(ctx context.Context, mailboxID string) (bool, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	total := db.Model(&domain.Message{}).Select("COUNT(*)").Where("mailbox_id = ?", mailboxID)
+	unread := db.Model(&domain.Message{}).Select("COUNT(*)").Where("mailbox_id = ? AND is_read = ?", mailboxID, false)
+	result := db.Model(&domain.Mailbox{}).
+		Where("id = ? AND (total_count <> (?) OR unread <> (?))", mailboxID, total, unread).
+		Updates(map[string]interface{}{
+			"total_count": total,
+			"unread":      unread,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
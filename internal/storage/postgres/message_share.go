@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Message Share Repository ==========
+
+// SaveMessageShare 保存邮件分享链接（覆盖已有记录）
+func (s *Store) SaveMessageShare(ctx context.Context, share *domain.MessageShare) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(share).Error
+}
+
+// GetMessageShare 获取邮件分享链接
+func (s *Store) GetMessageShare(ctx context.Context, id string) (*domain.MessageShare, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var share domain.MessageShare
+	if err := db.Where("id = ?", id).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrMessageShareNotFound
+		}
+		return nil, err
+	}
+	return &share, nil
+}
+
+// ListMessageShares 列出邮箱的分享链接（按创建时间倒序）
+func (s *Store) ListMessageShares(ctx context.Context, mailboxID string) ([]*domain.MessageShare, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var shares []*domain.MessageShare
+	if err := db.Where("mailbox_id = ?", mailboxID).Order("created_at DESC, id DESC").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// RecordMessageShareView 访问次数加一并记录访问时间（单条 UPDATE，并发访问不丢计数）
+func (s *Store) RecordMessageShareView(ctx context.Context, id string, at time.Time) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result := db.Model(&domain.MessageShare{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"views":          gorm.Expr("views + 1"),
+		"last_viewed_at": at,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrMessageShareNotFound
+	}
+	return nil
+}
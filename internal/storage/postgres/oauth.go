@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== OAuth Identity Repository ==========
+
+// CreateOAuthIdentity 关联第三方登录身份（依赖 (provider, subject) 唯一索引判断重复）
+func (s *Store) CreateOAuthIdentity(ctx context.Context, identity *domain.OAuthIdentity) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(identity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrOAuthIdentityExists
+	}
+	return nil
+}
+
+// GetOAuthIdentity 按提供方和提供方用户标识查找
+func (s *Store) GetOAuthIdentity(ctx context.Context, provider, subject string) (*domain.OAuthIdentity, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var identity domain.OAuthIdentity
+	if err := db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrOAuthIdentityNotFound
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// TouchOAuthIdentity 更新身份的最近登录时间
+func (s *Store) TouchOAuthIdentity(ctx context.Context, id string, at time.Time) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result := db.Model(&domain.OAuthIdentity{}).Where("id = ?", id).Update("last_login_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrOAuthIdentityNotFound
+	}
+	return nil
+}
+
+// ListOAuthIdentities 列出用户关联的第三方登录身份（按关联时间排序）
+func (s *Store) ListOAuthIdentities(ctx context.Context, userID string) ([]*domain.OAuthIdentity, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var identities []*domain.OAuthIdentity
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
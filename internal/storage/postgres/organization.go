@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Organization Repository ==========
+
+// CreateOrganization 创建组织
+func (s *Store) CreateOrganization(ctx context.Context, org *domain.Organization) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Create(org).Error
+}
+
+// GetOrganization 获取组织
+func (s *Store) GetOrganization(ctx context.Context, id string) (*domain.Organization, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var org domain.Organization
+	if err := db.Where("id = ?", id).First(&org).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// SaveOrgMember 保存组织成员（已存在时更新角色）
+func (s *Store) SaveOrgMember(ctx context.Context, member *domain.OrgMember) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "org_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(member).Error
+}
+
+// GetOrgMember 获取组织成员
+func (s *Store) GetOrgMember(ctx context.Context, orgID, userID string) (*domain.OrgMember, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var member domain.OrgMember
+	if err := db.Where("org_id = ? AND user_id = ?", orgID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrOrgMemberNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ListOrgMembers 列出组织的所有成员
+func (s *Store) ListOrgMembers(ctx context.Context, orgID string) ([]*domain.OrgMember, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var members []*domain.OrgMember
+	err := db.Where("org_id = ?", orgID).Order("joined_at").Find(&members).Error
+	return members, err
+}
+
+// ListOrgMembershipsByUserID 列出用户加入的所有组织成员关系
+func (s *Store) ListOrgMembershipsByUserID(ctx context.Context, userID string) ([]*domain.OrgMember, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var members []*domain.OrgMember
+	err := db.Where("user_id = ?", userID).Order("joined_at").Find(&members).Error
+	return members, err
+}
+
+// DeleteOrgMember 移除组织成员
+func (s *Store) DeleteOrgMember(ctx context.Context, orgID, userID string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result := db.Where("org_id = ? AND user_id = ?", orgID, userID).Delete(&domain.OrgMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrOrgMemberNotFound
+	}
+	return nil
+}
+
+// SaveOrgInvite 保存组织邀请
+func (s *Store) SaveOrgInvite(ctx context.Context, invite *domain.OrgInvite) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(invite).Error
+}
+
+// GetOrgInvite 根据加入令牌获取邀请
+func (s *Store) GetOrgInvite(ctx context.Context, token string) (*domain.OrgInvite, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var invite domain.OrgInvite
+	if err := db.Where("token = ?", token).First(&invite).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrOrgInviteNotFound
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// DeleteOrgInvite 删除邀请（接受后作废，并发接受时只有一个成功）
+func (s *Store) DeleteOrgInvite(ctx context.Context, token string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result := db.Where("token = ?", token).Delete(&domain.OrgInvite{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrOrgInviteNotFound
+	}
+	return nil
+}
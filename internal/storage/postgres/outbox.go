@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Outbox Repository ==========
+
+// SaveMessagesWithOutbox 在同一事务内批量插入邮件和发件箱事件
+func (s *Store) SaveMessagesWithOutbox(ctx context.Context, messages []*domain.Message, events []*domain.OutboxEvent) error {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	if len(messages) == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := saveMessages(tx, messages); err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(events, 100).Error
+	})
+}
+
+// ClaimOutboxEvents 认领可投递的事件
+//
+// 先选出候选，再按条件写入本次认领的令牌（条件更新逐行原子，并发认领时每行只有一方成功），
+// 最后按令牌读回认领到的事件。
+func (s *Store) ClaimOutboxEvents(ctx context.Context, now, until time.Time, limit int) ([]*domain.OutboxEvent, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	now = now.UTC()
+	claimable := "processed_at IS NULL AND (claimed_until IS NULL OR claimed_until < ?)"
+	var ids []string
+	if err := db.Model(&domain.OutboxEvent{}).
+		Where(claimable, now).
+		Where("next_attempt_at <= ?", now).
+		Order("created_at, id").
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	token := uuid.NewString()
+	result := db.Model(&domain.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Where(claimable, now).
+		Updates(map[string]interface{}{"claim_token": token, "claimed_until": until.UTC()})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var events []*domain.OutboxEvent
+	if err := db.Where("claim_token = ?", token).Order("created_at, id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// UpdateOutboxEvent 保存事件的投递进度
+func (s *Store) UpdateOutboxEvent(ctx context.Context, event *domain.OutboxEvent) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	return db.Model(event).
+		Select("delivered", "attempts", "last_error", "next_attempt_at", "claimed_until", "processed_at").
+		Updates(event).Error
+}
+
+// DeleteProcessedOutboxEvents 删除早于 before 处理完成的事件
+func (s *Store) DeleteProcessedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	result := db.Where("processed_at IS NOT NULL AND processed_at < ?", before.UTC()).Delete(&domain.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Redaction Repository ==========
+
+// SaveMessageRedaction 保存邮件脱敏副本（覆盖已有副本）
+func (s *Store) SaveMessageRedaction(ctx context.Context, redaction *domain.MessageRedaction) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(redaction).Error
+}
+
+// GetMessageRedaction 获取邮件脱敏副本
+func (s *Store) GetMessageRedaction(ctx context.Context, mailboxID, messageID string) (*domain.MessageRedaction, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var redaction domain.MessageRedaction
+	err := db.Where("message_id = ? AND mailbox_id = ?", messageID, mailboxID).First(&redaction).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrRedactionNotFound
+		}
+		return nil, err
+	}
+	return &redaction, nil
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+
+	"gorm.io/gorm/clause"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== Reserved Prefix Repository ==========
+
+// CreateReservedPrefix 添加保留前缀（依赖 (domain, local_part) 唯一索引判断重复）
+func (s *Store) CreateReservedPrefix(ctx context.Context, prefix *domain.ReservedPrefix) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(prefix)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrReservedPrefixExists
+	}
+	return nil
+}
+
+// ListReservedPrefixes 列出保留前缀（按域名、前缀排序）
+func (s *Store) ListReservedPrefixes(ctx context.Context, userID *string) ([]*domain.ReservedPrefix, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	query := db.Order("domain").Order("local_part")
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	var prefixes []*domain.ReservedPrefix
+	if err := query.Find(&prefixes).Error; err != nil {
+		return nil, err
+	}
+	return prefixes, nil
+}
+
+// FindReservedPrefixes 查找前缀在指定域名及所有系统域名上的条目
+func (s *Store) FindReservedPrefixes(ctx context.Context, domainName, localPart string) ([]*domain.ReservedPrefix, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var prefixes []*domain.ReservedPrefix
+	if err := db.Where("local_part = ? AND domain IN ?", localPart, []string{"", domainName}).
+		Find(&prefixes).Error; err != nil {
+		return nil, err
+	}
+	return prefixes, nil
+}
+
+// DeleteReservedPrefix 删除保留前缀
+func (s *Store) DeleteReservedPrefix(ctx context.Context, id string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result := db.Where("id = ?", id).Delete(&domain.ReservedPrefix{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrReservedPrefixNotFound
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"tempmail/backend/internal/domain"
+)
+
+// IndexMessages 写入或覆盖邮件的索引文档
+func (s *Store) IndexMessages(ctx context.Context, docs []*domain.SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "message_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mailbox_id", "terms", "received_at"}),
+	}).CreateInBatches(docs, 100).Error
+}
+
+// SearchIndex 查找命中全部词项的邮件
+//
+// PostgreSQL 使用 to_tsvector('simple', terms) 的 GIN 表达式索引；其他方言按整词 LIKE 匹配。
+// 与 messages 连接，已删除和隔离区中的邮件不返回。
+func (s *Store) SearchIndex(ctx context.Context, query domain.IndexQuery) (*domain.MessageSearchResult, error) {
+	query.Normalize()
+	result := &domain.MessageSearchResult{Messages: []domain.Message{}, Page: query.Page, PageSize: query.PageSize}
+	if len(query.Terms) == 0 || len(query.MailboxIDs) == 0 {
+		return result, nil
+	}
+
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	scope := func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Table("message_search_documents AS d").
+			Joins("JOIN messages ON messages.id = d.message_id").
+			Where("d.mailbox_id IN ? AND messages.quarantined = ?", query.MailboxIDs, false)
+		if s.db.Dialector.Name() == "postgres" {
+			// 词项只含字母和数字，可直接用 & 拼成 tsquery
+			return tx.Where("to_tsvector('simple', d.terms) @@ to_tsquery('simple', ?)", strings.Join(query.Terms, " & "))
+		}
+		for _, term := range query.Terms {
+			tx = tx.Where("d.terms LIKE ?", "% "+term+" %")
+		}
+		return tx
+	}
+
+	var total int64
+	if err := db.Scopes(scope).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count indexed messages: %w", err)
+	}
+	if err := db.Scopes(scope).
+		Select("messages.*").
+		Order("d.received_at DESC, d.message_id DESC").
+		Limit(query.PageSize).
+		Offset((query.Page - 1) * query.PageSize).
+		Find(&result.Messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+
+	result.Total = int(total)
+	result.TotalPages = (result.Total + query.PageSize - 1) / query.PageSize
+	return result, nil
+}
+
+// ClearSearchIndex 清空索引
+func (s *Store) ClearSearchIndex(ctx context.Context) error {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&domain.SearchDocument{}).Error
+}
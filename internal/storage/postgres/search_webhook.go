@@ -1,14 +1,21 @@
 package postgres
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"tempmail/backend/internal/domain"
 )
 
 // SearchMessages 搜索邮件（PostgreSQL 实现，支持全文搜索）
-func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error) {
+func (s *Store) SearchMessages(ctx context.Context, criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	// 设置默认分页参数
 	if criteria.Page <= 0 {
 		criteria.Page = 1
@@ -21,26 +28,24 @@ func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.M
 	}
 
 	// 构建查询
-	query := s.db.Model(&domain.Message{}).Where("mailbox_id = ?", criteria.MailboxID)
+	query := db.Model(&domain.Message{}).Where("mailbox_id = ?", criteria.MailboxID)
 
-	// 关键词搜索（使用 LIKE 进行不区分大小写搜索，兼容MySQL和PostgreSQL）
+	// 关键词搜索（LOWER + LIKE 进行不区分大小写搜索，兼容 MySQL、PostgreSQL 和 SQLite）
+	// 正文保存在文件系统中，数据库只能匹配主题和发件人；from 是保留字，通过 clause.Column 按方言加引号
+	from := clause.Column{Name: "from"}
 	if criteria.Query != "" {
-		searchPattern := "%" + criteria.Query + "%"
-		// 使用GORM字段名而不是数据库列名
-		query = query.Where(
-			"subject LIKE ? OR `from` LIKE ? OR text LIKE ?",
-			searchPattern, searchPattern, searchPattern,
-		)
+		searchPattern := likePattern(criteria.Query)
+		query = query.Where("LOWER(subject) LIKE ? OR LOWER(?) LIKE ?", searchPattern, from, searchPattern)
 	}
 
 	// 发件人筛选
 	if criteria.From != "" {
-		query = query.Where("`from` LIKE ?", "%"+criteria.From+"%")
+		query = query.Where("LOWER(?) LIKE ?", from, likePattern(criteria.From))
 	}
 
-	// 主题筛选（MySQL的LIKE默认不区分大小写）
+	// 主题筛选
 	if criteria.Subject != "" {
-		query = query.Where("subject LIKE ?", "%"+criteria.Subject+"%")
+		query = query.Where("LOWER(subject) LIKE ?", likePattern(criteria.Subject))
 	}
 
 	// 时间范围筛选
@@ -67,6 +72,32 @@ func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.M
 		}
 	}
 
+	// 语言筛选
+	if criteria.Language != "" {
+		query = query.Where("detected_language = ?", strings.ToLower(criteria.Language))
+	}
+
+	// 垃圾邮件评分筛选
+	if criteria.SpamScoreGte != nil {
+		query = query.Where("spam_score >= ?", *criteria.SpamScoreGte)
+	}
+	if criteria.IsSpam != nil {
+		query = query.Where("is_spam = ?", *criteria.IsSpam)
+	}
+
+	// DKIM 验证结果筛选
+	if criteria.DKIMResult != "" {
+		query = query.Where("dkim_result = ?", criteria.DKIMResult)
+	}
+
+	// 保存的头筛选
+	if len(criteria.Headers) > 0 {
+		var err error
+		if query, err = s.whereHeaders(query, criteria.Headers); err != nil {
+			return nil, err
+		}
+	}
+
 	// 获取总数
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -77,7 +108,7 @@ func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.M
 	var messages []domain.Message
 	offset := (criteria.Page - 1) * criteria.PageSize
 	if err := query.
-		Order("created_at DESC").
+		Order("seq DESC, created_at DESC").
 		Limit(criteria.PageSize).
 		Offset(offset).
 		Find(&messages).Error; err != nil {
@@ -96,42 +127,147 @@ func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.M
 		Page:       criteria.Page,
 		PageSize:   criteria.PageSize,
 		TotalPages: totalPages,
+		Snippets:   s.searchHeadlines(db, messages, criteria),
 	}, nil
 }
 
+// whereHeaders 保存的头精确匹配：PostgreSQL 使用 JSONB 包含查询（可走 GIN 索引），其他方言按 JSON 路径取值比较
+func (s *Store) whereHeaders(query *gorm.DB, headers map[string]string) (*gorm.DB, error) {
+	if s.db.Dialector.Name() == "postgres" {
+		filter, err := json.Marshal(headers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode header filter: %w", err)
+		}
+		return query.Where("captured_headers::jsonb @> ?::jsonb", string(filter)), nil
+	}
+	for name, value := range headers {
+		// 头名只含字母、数字、连字符和下划线，可直接拼入 JSON 路径
+		if !domain.ValidHeaderName(name) {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+		query = query.Where("JSON_EXTRACT(captured_headers, ?) = ?", `$."`+name+`"`, value)
+	}
+	return query, nil
+}
+
+// likePattern 构造不区分大小写的包含匹配模式
+func likePattern(keyword string) string {
+	return "%" + strings.ToLower(keyword) + "%"
+}
+
+// searchHeadlines 使用 ts_headline 生成主题和发件人摘要（仅 PostgreSQL）
+//
+// 命中位置先用哨兵字符标记，再交给 domain.FinalizeSnippet 转义并替换为最终标记，
+// 与 Go 提取器的输出格式保持一致。查询失败时返回 nil，由服务层回退到 Go 提取器。
+func (s *Store) searchHeadlines(db *gorm.DB, messages []domain.Message, criteria domain.MessageSearchCriteria) map[string]domain.MessageSnippets {
+	if criteria.Highlight == nil || criteria.Query == "" || len(messages) == 0 {
+		return nil
+	}
+	if s.db.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	ids := make([]string, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+
+	options := fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=35, MinWords=15, MaxFragments=1`,
+		domain.SnippetStartSentinel, domain.SnippetStopSentinel)
+
+	var rows []struct {
+		ID       string
+		Subject  string
+		FromAddr string
+	}
+	err := db.Raw(`SELECT id,
+		ts_headline('simple', coalesce(subject, ''), plainto_tsquery('simple', ?), ?) AS subject,
+		ts_headline('simple', coalesce("from", ''), plainto_tsquery('simple', ?), ?) AS from_addr
+		FROM messages WHERE id IN ?`,
+		criteria.Query, options, criteria.Query, options, ids,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil
+	}
+
+	snippets := make(map[string]domain.MessageSnippets, len(rows))
+	for _, row := range rows {
+		item := domain.MessageSnippets{
+			Subject: domain.FinalizeSnippet(row.Subject, *criteria.Highlight),
+			From:    domain.FinalizeSnippet(row.FromAddr, *criteria.Highlight),
+		}
+		if !item.IsEmpty() {
+			snippets[row.ID] = item
+		}
+	}
+	return snippets
+}
+
 // ========== Webhook Repository ==========
 
 // CreateWebhook 创建 Webhook
-func (s *Store) CreateWebhook(webhook *domain.Webhook) error {
-	return s.db.Create(webhook).Error
+func (s *Store) CreateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Create(webhook).Error
 }
 
 // GetWebhook 获取 Webhook
-func (s *Store) GetWebhook(id string) (*domain.Webhook, error) {
+func (s *Store) GetWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var webhook domain.Webhook
-	if err := s.db.Where("id = ?", id).First(&webhook).Error; err != nil {
+	if err := db.Where("id = ?", id).First(&webhook).Error; err != nil {
 		return nil, fmt.Errorf("webhook not found: %w", err)
 	}
 	return &webhook, nil
 }
 
-// ListWebhooks 列出用户的 Webhooks
-func (s *Store) ListWebhooks(userID string) ([]domain.Webhook, error) {
+// ListWebhooks 列出用户的 Webhooks（不含邮箱 Webhook）
+func (s *Store) ListWebhooks(ctx context.Context, userID string) ([]domain.Webhook, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var webhooks []domain.Webhook
+	if err := db.Where("user_id = ? AND owner_type <> ?", userID, domain.WebhookOwnerMailbox).Order("created_at DESC").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// ListWebhooksByMailbox 列出邮箱 Webhooks
+func (s *Store) ListWebhooksByMailbox(ctx context.Context, mailboxID string) ([]domain.Webhook, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var webhooks []domain.Webhook
+	if err := db.Where("owner_type = ? AND owner_id = ?", domain.WebhookOwnerMailbox, mailboxID).Order("created_at ASC").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// ListWebhooksByOrgID 列出组织的所有 Webhook
+func (s *Store) ListWebhooksByOrgID(ctx context.Context, orgID string) ([]domain.Webhook, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var webhooks []domain.Webhook
-	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&webhooks).Error; err != nil {
+	if err := db.Where("org_id = ?", orgID).Order("created_at DESC").Find(&webhooks).Error; err != nil {
 		return nil, err
 	}
 	return webhooks, nil
 }
 
 // UpdateWebhook 更新 Webhook
-func (s *Store) UpdateWebhook(webhook *domain.Webhook) error {
-	return s.db.Save(webhook).Error
+func (s *Store) UpdateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(webhook).Error
 }
 
 // DeleteWebhook 删除 Webhook
-func (s *Store) DeleteWebhook(id string) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
 		// 删除投递记录
 		if err := tx.Where("webhook_id = ?", id).Delete(&domain.WebhookDelivery{}).Error; err != nil {
 			return err
@@ -142,14 +278,18 @@ func (s *Store) DeleteWebhook(id string) error {
 }
 
 // RecordDelivery 记录投递
-func (s *Store) RecordDelivery(delivery *domain.WebhookDelivery) error {
-	return s.db.Create(delivery).Error
+func (s *Store) RecordDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Create(delivery).Error
 }
 
 // GetDeliveries 获取投递记录
-func (s *Store) GetDeliveries(webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+func (s *Store) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var deliveries []domain.WebhookDelivery
-	if err := s.db.
+	if err := db.
 		Where("webhook_id = ?", webhookID).
 		Order("created_at DESC").
 		Limit(limit).
@@ -160,10 +300,12 @@ func (s *Store) GetDeliveries(webhookID string, limit int) ([]domain.WebhookDeli
 }
 
 // GetPendingDeliveries 获取待重试投递
-func (s *Store) GetPendingDeliveries(limit int) ([]domain.WebhookDelivery, error) {
+func (s *Store) GetPendingDeliveries(ctx context.Context, limit int) ([]domain.WebhookDelivery, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var deliveries []domain.WebhookDelivery
-	if err := s.db.
-		Where("success = ? AND next_retry IS NOT NULL AND next_retry <= NOW()", false).
+	if err := db.
+		Where("success = ? AND next_retry IS NOT NULL AND next_retry <= ?", false, time.Now().UTC()).
 		Order("next_retry ASC").
 		Limit(limit).
 		Find(&deliveries).Error; err != nil {
@@ -171,3 +313,46 @@ func (s *Store) GetPendingDeliveries(limit int) ([]domain.WebhookDelivery, error
 	}
 	return deliveries, nil
 }
+
+// CancelPendingDeliveries 取消指定邮箱尚未完成的重试投递，返回取消数量
+func (s *Store) CancelPendingDeliveries(ctx context.Context, mailboxID string) (int, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	result := db.Model(&domain.WebhookDelivery{}).
+		Where("mailbox_id = ? AND success = ? AND next_retry IS NOT NULL", mailboxID, false).
+		Update("next_retry", nil)
+	return int(result.RowsAffected), result.Error
+}
+
+// GetDelivery 获取投递记录
+func (s *Store) GetDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var delivery domain.WebhookDelivery
+	if err := db.Where("id = ?", id).First(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// UpdateDelivery 更新投递记录
+func (s *Store) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(delivery).Error
+}
+
+// ListDeadLetters 列出尚未重新投递的死信（webhookID 为空时列出全部）
+func (s *Store) ListDeadLetters(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	query := db.Where("dead_letter = ? AND redelivered_at IS NULL", true)
+	if webhookID != "" {
+		query = query.Where("webhook_id = ?", webhookID)
+	}
+	var deliveries []domain.WebhookDelivery
+	if err := query.Order("created_at DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"tempmail/backend/internal/domain"
+)
+
+// ========== Sent Message Repository ==========
+
+// SaveSentMessage 保存已发送邮件
+func (s *Store) SaveSentMessage(ctx context.Context, message *domain.SentMessage) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Create(message).Error
+}
+
+// ListSentMessages 列出邮箱发出的邮件（按发送时间倒序）
+func (s *Store) ListSentMessages(ctx context.Context, mailboxID string) ([]*domain.SentMessage, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var messages []*domain.SentMessage
+	if err := db.Where("mailbox_id = ?", mailboxID).Order("sent_at DESC, id DESC").Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// CountSentMessages 按邮箱或用户统计 Since 之后发出的邮件数及其中最早的发送时间
+func (s *Store) CountSentMessages(ctx context.Context, filter domain.SentMessageFilter) (domain.SentMessageUsage, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	query := db.Model(&domain.SentMessage{}).Where("sent_at > ?", filter.Since)
+	if filter.MailboxID != "" {
+		query = query.Where("mailbox_id = ?", filter.MailboxID)
+	}
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	// MIN(sent_at) 在 SQLite 上丢失列类型（返回字符串），最早时间单独查询
+	var usage domain.SentMessageUsage
+	if err := query.Session(&gorm.Session{}).Count(&usage.Count).Error; err != nil || usage.Count == 0 {
+		return usage, err
+	}
+	var oldest domain.SentMessage
+	if err := query.Select("sent_at").Order("sent_at ASC").Take(&oldest).Error; err != nil {
+		return domain.SentMessageUsage{}, err
+	}
+	usage.OldestAt = oldest.SentAt
+	return usage, nil
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+// SlowQuerySink 接收慢 SQL（sql 为模板，不含参数值）
+type SlowQuerySink interface {
+	RecordSlowQuery(sql string, elapsed time.Duration, err error)
+}
+
+// SetSlowQueryLog 开启慢 SQL 记录：耗时超过 threshold 的语句交给 sink，其余日志仍保持静默
+func (s *Store) SetSlowQueryLog(threshold time.Duration, sink SlowQuerySink) {
+	s.db.Logger = &slowQueryLogger{threshold: threshold, sink: sink}
+}
+
+// slowQueryLogger 只记录慢 SQL 的 GORM 日志
+type slowQueryLogger struct {
+	threshold time.Duration
+	sink      SlowQuerySink
+}
+
+func (l *slowQueryLogger) LogMode(logger.LogLevel) logger.Interface { return l }
+
+func (l *slowQueryLogger) Info(context.Context, string, ...interface{})  {}
+func (l *slowQueryLogger) Warn(context.Context, string, ...interface{})  {}
+func (l *slowQueryLogger) Error(context.Context, string, ...interface{}) {}
+
+// Trace 语句执行完成后调用，只在超过阈值时生成 SQL 文本
+func (l *slowQueryLogger) Trace(_ context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	if elapsed < l.threshold {
+		return
+	}
+	sql, _ := fc()
+	l.sink.RecordSlowQuery(sql, elapsed, err)
+}
+
+// ParamsFilter 丢弃参数值，GORM 生成的 SQL 只保留占位符模板
+func (l *slowQueryLogger) ParamsFilter(_ context.Context, sql string, _ ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// sqlitePragmas 每个连接打开时执行的 PRAGMA
+//
+// WAL 让读写互不阻塞；busy_timeout 在文件被其他进程（如迁移工具）锁住时等待而不是立即失败；
+// _txlock=immediate 让写事务开始时就拿到写锁，避免事务中途升级锁导致 SQLITE_BUSY。
+var sqlitePragmas = []string{
+	"_pragma=journal_mode(WAL)",
+	"_pragma=busy_timeout(5000)",
+	"_pragma=foreign_keys(1)",
+	"_txlock=immediate",
+}
+
+// NewSQLiteStore 创建 SQLite 存储实例（单文件部署，不依赖外部数据库）
+//
+// dsn 为数据库文件路径，可带查询参数。SQLite 同一时刻只允许一个写者，
+// 连接池限制为 1 个连接，所有读写在进程内串行执行。
+// 没有独立的迁移步骤，打开时自动建表（migrations/sqlite 中的脚本供手动部署参考）。
+func NewSQLiteStore(dsn string) (*Store, error) {
+	// 数据库文件所在目录不存在时先创建
+	if path, _, _ := strings.Cut(dsn, "?"); path != "" && path != ":memory:" && !strings.HasPrefix(path, "file:") {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create sqlite directory: %w", err)
+		}
+	}
+
+	sqlDB, err := sql.Open(sqlite.DriverName, sqliteDSN(dsn))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetMaxIdleConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+
+	db, err := gorm.Open(sqlite.Dialector{Conn: &utcConnPool{DB: sqlDB}}, gormConfig())
+	if err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	store := &Store{db: db}
+	if err := store.migrate(); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to migrate sqlite database: %w", err)
+	}
+	return store, nil
+}
+
+// sqliteDSN 在 DSN 上追加默认 PRAGMA（已显式指定的不覆盖）
+func sqliteDSN(dsn string) string {
+	params := make([]string, 0, len(sqlitePragmas))
+	for _, pragma := range sqlitePragmas {
+		name := strings.TrimPrefix(pragma, "_pragma=")
+		name = name[:strings.IndexAny(name, "(=")]
+		if !strings.Contains(dsn, name) {
+			params = append(params, pragma)
+		}
+	}
+	if len(params) == 0 {
+		return dsn
+	}
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + strings.Join(params, "&")
+}
+
+// ========== UTC 时间参数 ==========
+//
+// SQLite 没有时间类型，时间以带时区偏移的文本保存，比较时按字符串比较。
+// 调用方传入的 time.Now() 是本地时区，和库里的 UTC 时间直接比较会出错，
+// 因此所有查询参数中的时间都先转换为 UTC。
+
+// utcConnPool 将查询参数中的时间转换为 UTC 的连接池
+type utcConnPool struct {
+	*sql.DB
+}
+
+func (p *utcConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.DB.ExecContext(ctx, query, utcArgs(args)...)
+}
+
+func (p *utcConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.DB.QueryContext(ctx, query, utcArgs(args)...)
+}
+
+func (p *utcConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.DB.QueryRowContext(ctx, query, utcArgs(args)...)
+}
+
+// BeginTx 开启事务，事务内的查询同样转换时间参数
+func (p *utcConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &utcTx{Tx: tx}, nil
+}
+
+// GetDBConn 供 gorm.DB.DB() 取得底层连接
+func (p *utcConnPool) GetDBConn() (*sql.DB, error) {
+	return p.DB, nil
+}
+
+// utcTx 将查询参数中的时间转换为 UTC 的事务
+type utcTx struct {
+	*sql.Tx
+}
+
+func (t *utcTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, query, utcArgs(args)...)
+}
+
+func (t *utcTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, query, utcArgs(args)...)
+}
+
+func (t *utcTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.Tx.QueryRowContext(ctx, query, utcArgs(args)...)
+}
+
+func utcArgs(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case *time.Time:
+			if v != nil {
+				utc := v.UTC()
+				args[i] = &utc
+			}
+		}
+	}
+	return args
+}
@@ -0,0 +1,942 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// newSQLiteTestStore 在临时目录中创建 SQLite 存储
+func newSQLiteTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tempmail.db")
+	store, err := NewSQLiteStore(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store, path
+}
+
+func newSQLiteMailbox(t *testing.T, store *Store, userID string, expiresAt *time.Time) *domain.Mailbox {
+	t.Helper()
+	suffix := uuid.NewString()[:8]
+	mailbox := &domain.Mailbox{
+		ID: uuid.NewString(), Address: "box-" + suffix + "@corp.example", LocalPart: "box-" + suffix,
+		Domain: "corp.example", Token: "tok-" + suffix, CreatedAt: time.Now(), ExpiresAt: expiresAt,
+	}
+	if userID != "" {
+		mailbox.UserID = &userID
+	}
+	require.NoError(t, store.SaveMailbox(t.Context(), mailbox))
+	return mailbox
+}
+
+func TestSQLiteStore(t *testing.T) {
+	store, path := newSQLiteTestStore(t)
+
+	t.Run("连接参数", func(t *testing.T) {
+		var journal string
+		require.NoError(t, store.db.Raw("PRAGMA journal_mode").Scan(&journal).Error)
+		assert.Equal(t, "wal", journal)
+
+		sqlDB, err := store.db.DB()
+		require.NoError(t, err)
+		assert.Equal(t, 1, sqlDB.Stats().MaxOpenConnections)
+	})
+
+	t.Run("默认参数不覆盖显式设置", func(t *testing.T) {
+		dsn := sqliteDSN(path + "?_pragma=busy_timeout(100)")
+		assert.Contains(t, dsn, "busy_timeout(100)")
+		assert.NotContains(t, dsn, "busy_timeout(5000)")
+		assert.Contains(t, dsn, "journal_mode(WAL)")
+	})
+
+	t.Run("过期判断不受本地时区影响", func(t *testing.T) {
+		// 本地时区的时间和库里的 UTC 文本比较时，必须先换算成 UTC
+		east := time.FixedZone("UTC+8", 8*3600)
+		future := time.Now().Add(time.Hour).In(east)
+		past := time.Now().Add(-time.Hour).In(east)
+		active := newSQLiteMailbox(t, store, "", &future)
+		expired := newSQLiteMailbox(t, store, "", &past)
+
+		_, err := store.GetMailbox(t.Context(), active.ID)
+		assert.NoError(t, err)
+		_, err = store.GetMailbox(t.Context(), expired.ID)
+		assert.ErrorIs(t, err, storage.ErrMailboxNotFound)
+
+		deleted, err := store.DeleteExpiredMailboxes(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+	})
+
+	t.Run("邮件统计按 UTC 小时分桶", func(t *testing.T) {
+		mailbox := newSQLiteMailbox(t, store, "", nil)
+		hour := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+		for i, at := range []time.Time{hour.Add(5 * time.Minute), hour.Add(50 * time.Minute), hour.Add(time.Hour + time.Minute)} {
+			require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+				ID: uuid.NewString(), MailboxID: mailbox.ID, From: "Alice@Example.com", Subject: "stats",
+				Size: int64(100 * (i + 1)), ReceivedAt: at, CreatedAt: at,
+			}))
+		}
+
+		stats, err := store.GetMessageStats(t.Context(), domain.MessageStatsQuery{
+			MailboxIDs: []string{mailbox.ID}, Since: hour.Add(-time.Hour), Until: time.Now(), TopSenders: 5,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, stats.Total)
+		require.Len(t, stats.Hourly, 2)
+		assert.Equal(t, domain.HourlyCount{Hour: hour, Count: 2}, stats.Hourly[0])
+		assert.Equal(t, domain.HourlyCount{Hour: hour.Add(time.Hour), Count: 1}, stats.Hourly[1])
+		require.Len(t, stats.TopSenders, 1)
+		assert.Equal(t, "alice@example.com", stats.TopSenders[0].Sender)
+	})
+
+	t.Run("搜索回退到不区分大小写的 LIKE", func(t *testing.T) {
+		mailbox := newSQLiteMailbox(t, store, "", nil)
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+			ID: uuid.NewString(), MailboxID: mailbox.ID, From: "billing@shop.example", Subject: "Your Invoice",
+			ReceivedAt: time.Now(), CreatedAt: time.Now(),
+		}))
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+			ID: uuid.NewString(), MailboxID: mailbox.ID, From: "news@shop.example", Subject: "Weekly digest",
+			ReceivedAt: time.Now(), CreatedAt: time.Now(),
+		}))
+
+		result, err := store.SearchMessages(t.Context(), domain.MessageSearchCriteria{MailboxID: mailbox.ID, Query: "invoice"})
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Total)
+		assert.Equal(t, "Your Invoice", result.Messages[0].Subject)
+
+		result, err = store.SearchMessages(t.Context(), domain.MessageSearchCriteria{MailboxID: mailbox.ID, From: "NEWS@"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Total)
+	})
+
+	t.Run("按保存的头精确过滤", func(t *testing.T) {
+		mailbox := newSQLiteMailbox(t, store, "", nil)
+		for run, subject := range map[string]string{"4711": "run 4711", "4712": "run 4712"} {
+			require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+				ID: uuid.NewString(), MailboxID: mailbox.ID, Subject: subject,
+				CapturedHeaders: map[string]string{"X-Test-Run-Id": run, "X-Correlation-Id": "ci"},
+				ReceivedAt:      time.Now(), CreatedAt: time.Now(),
+			}))
+		}
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+			ID: uuid.NewString(), MailboxID: mailbox.ID, Subject: "no headers", ReceivedAt: time.Now(), CreatedAt: time.Now(),
+		}))
+
+		result, err := store.SearchMessages(t.Context(), domain.MessageSearchCriteria{MailboxID: mailbox.ID, Headers: map[string]string{"X-Test-Run-Id": "4711"}})
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Total)
+		assert.Equal(t, "run 4711", result.Messages[0].Subject)
+		assert.Equal(t, "ci", result.Messages[0].CapturedHeaders["X-Correlation-Id"])
+
+		result, err = store.SearchMessages(t.Context(), domain.MessageSearchCriteria{MailboxID: mailbox.ID, Headers: map[string]string{"X-Test-Run-Id": "4711", "X-Correlation-Id": "other"}})
+		require.NoError(t, err)
+		assert.Zero(t, result.Total, "所有条件都须匹配")
+	})
+
+	t.Run("到期的重试投递", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		future := time.Now().Add(time.Hour)
+		due := &domain.WebhookDelivery{ID: uuid.NewString(), WebhookID: uuid.NewString(), NextRetry: &past}
+		require.NoError(t, store.RecordDelivery(t.Context(), due))
+		require.NoError(t, store.RecordDelivery(t.Context(), &domain.WebhookDelivery{ID: uuid.NewString(), WebhookID: uuid.NewString(), NextRetry: &future}))
+
+		pending, err := store.GetPendingDeliveries(t.Context(), 10)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, due.ID, pending[0].ID)
+	})
+
+	t.Run("死信和重新投递", func(t *testing.T) {
+		webhookID := uuid.NewString()
+		dead := &domain.WebhookDelivery{ID: uuid.NewString(), WebhookID: webhookID, Payload: `{}`, Attempts: 5, DeadLetter: true}
+		require.NoError(t, store.RecordDelivery(t.Context(), dead))
+		require.NoError(t, store.RecordDelivery(t.Context(), &domain.WebhookDelivery{ID: uuid.NewString(), WebhookID: uuid.NewString(), DeadLetter: true}))
+
+		listed, err := store.ListDeadLetters(t.Context(), webhookID, 10)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, dead.ID, listed[0].ID)
+
+		got, err := store.GetDelivery(t.Context(), dead.ID)
+		require.NoError(t, err)
+		now := time.Now()
+		got.RedeliveredAt = &now
+		require.NoError(t, store.UpdateDelivery(t.Context(), got))
+
+		listed, err = store.ListDeadLetters(t.Context(), webhookID, 10)
+		require.NoError(t, err)
+		assert.Empty(t, listed)
+		all, err := store.ListDeadLetters(t.Context(), "", 10)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("单连接下删除用户不会死锁", func(t *testing.T) {
+		user := &domain.User{Email: uuid.NewString() + "@corp.example"}
+		require.NoError(t, store.CreateUser(t.Context(), user))
+		mailbox := newSQLiteMailbox(t, store, user.ID, nil)
+
+		done := make(chan error, 1)
+		go func() { done <- store.DeleteUser(t.Context(), user.ID) }()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("DeleteUser 超时")
+		}
+
+		_, err := store.GetUserByID(t.Context(), user.ID)
+		assert.Error(t, err)
+		_, err = store.GetMailbox(t.Context(), mailbox.ID)
+		assert.ErrorIs(t, err, storage.ErrMailboxNotFound)
+	})
+
+	t.Run("事务回滚", func(t *testing.T) {
+		mailbox := &domain.Mailbox{ID: uuid.NewString(), Address: "tx@corp.example", LocalPart: "tx", Domain: "corp.example", Token: "tok-tx"}
+		err := store.WithTransaction(func(tx *Store) error {
+			require.NoError(t, tx.SaveMailbox(t.Context(), mailbox))
+			return assert.AnError
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+		_, err = store.GetMailbox(t.Context(), mailbox.ID)
+		assert.ErrorIs(t, err, storage.ErrMailboxNotFound)
+	})
+
+	t.Run("并发写入串行执行", func(t *testing.T) {
+		mailbox := newSQLiteMailbox(t, store, "", nil)
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- store.SaveMessage(t.Context(), &domain.Message{
+					ID: uuid.NewString(), MailboxID: mailbox.ID, Subject: "concurrent", ReceivedAt: time.Now(), CreatedAt: time.Now(),
+				})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		got, err := store.GetMailbox(t.Context(), mailbox.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 20, got.TotalCount)
+	})
+
+	t.Run("批量查询邮箱与批量入库", func(t *testing.T) {
+		first := newSQLiteMailbox(t, store, "", nil)
+		second := newSQLiteMailbox(t, store, "", nil)
+		past := time.Now().Add(-time.Hour)
+		expired := newSQLiteMailbox(t, store, "", &past)
+
+		found, err := store.GetMailboxesByAddresses(t.Context(), []string{first.Address, second.Address, expired.Address, "ghost@corp.example"})
+		require.NoError(t, err)
+		assert.Len(t, found, 2, "过期和不存在的地址被跳过")
+
+		newMessage := func(mailboxID string) *domain.Message {
+			return &domain.Message{ID: uuid.NewString(), MailboxID: mailboxID, Subject: "batch", ReceivedAt: time.Now(), CreatedAt: time.Now()}
+		}
+		require.NoError(t, store.SaveMessages(t.Context(), []*domain.Message{newMessage(first.ID), newMessage(first.ID), newMessage(second.ID)}))
+		got, err := store.GetMailbox(t.Context(), first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, got.TotalCount)
+		assert.Equal(t, 2, got.Unread)
+
+		// 任一邮箱不存在时整批回滚
+		err = store.SaveMessages(t.Context(), []*domain.Message{newMessage(second.ID), newMessage(uuid.NewString())})
+		assert.ErrorIs(t, err, storage.ErrMailboxNotFound)
+		messages, err := store.ListMessages(t.Context(), second.ID)
+		require.NoError(t, err)
+		assert.Len(t, messages, 1)
+	})
+
+	t.Run("列出未过期的公开收件箱", func(t *testing.T) {
+		public := newSQLiteMailbox(t, store, "", nil)
+		public.IsPublic = true
+		require.NoError(t, store.SaveMailbox(t.Context(), public))
+		past := time.Now().Add(-time.Hour)
+		expired := newSQLiteMailbox(t, store, "", &past)
+		expired.IsPublic = true
+		require.NoError(t, store.SaveMailbox(t.Context(), expired))
+		newSQLiteMailbox(t, store, "", nil) // 私有邮箱不列出
+
+		listed, err := store.ListPublicMailboxes(t.Context(), time.Now())
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, public.ID, listed[0].ID)
+	})
+
+	t.Run("闲置标记与访问恢复有效期", func(t *testing.T) {
+		user := &domain.User{Email: uuid.NewString() + "@corp.example"}
+		require.NoError(t, store.CreateUser(t.Context(), user))
+		original := time.Now().Add(90 * 24 * time.Hour).UTC().Truncate(time.Second)
+		mailbox := newSQLiteMailbox(t, store, user.ID, &original)
+		newSQLiteMailbox(t, store, "", &original) // 游客邮箱不参与
+
+		now := time.Now().Add(31 * 24 * time.Hour)
+		idle, err := store.ListIdleMailboxes(t.Context(), now.Add(-30*24*time.Hour), now)
+		require.NoError(t, err)
+		require.Len(t, idle, 1)
+		assert.Equal(t, mailbox.ID, idle[0].ID)
+
+		shortened := now.Add(72 * time.Hour).UTC().Truncate(time.Second)
+		require.NoError(t, store.MarkMailboxIdle(t.Context(), mailbox.ID, now, &shortened))
+		got, err := store.GetMailbox(t.Context(), mailbox.ID)
+		require.NoError(t, err)
+		assert.True(t, got.IdleShortened)
+		assert.True(t, shortened.Equal(*got.ExpiresAt))
+		assert.True(t, original.Equal(*got.IdleOriginalExpiresAt))
+
+		idle, err = store.ListIdleMailboxes(t.Context(), now.Add(-30*24*time.Hour), now)
+		require.NoError(t, err)
+		assert.Empty(t, idle, "已标记的邮箱不再重复处理")
+
+		require.NoError(t, store.TouchMailbox(t.Context(), mailbox.ID, now))
+		got, err = store.GetMailbox(t.Context(), mailbox.ID)
+		require.NoError(t, err)
+		assert.True(t, original.Equal(*got.ExpiresAt))
+		assert.False(t, got.IdleShortened)
+		assert.Nil(t, got.IdleSince)
+		assert.Nil(t, got.IdleOriginalExpiresAt)
+		require.NotNil(t, got.LastAccessedAt)
+
+		assert.ErrorIs(t, store.TouchMailbox(t.Context(), uuid.NewString(), now), storage.ErrMailboxNotFound)
+	})
+
+	t.Run("黑洞模式设置随域名保存", func(t *testing.T) {
+		sysDomain := &domain.SystemDomain{
+			ID: uuid.NewString(), Domain: "sink.example", IsActive: true,
+			Sink: domain.SinkSettings{Enabled: true, SampleRate: 10, DiagnosticsMailboxID: uuid.NewString()},
+		}
+		require.NoError(t, store.SaveSystemDomain(t.Context(), sysDomain))
+
+		got, err := store.GetSystemDomainByDomain(t.Context(), "sink.example")
+		require.NoError(t, err)
+		assert.Equal(t, sysDomain.Sink, got.Sink)
+	})
+}
+
+// recordingSlowQuerySink 记录慢 SQL
+type recordingSlowQuerySink struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (s *recordingSlowQuerySink) RecordSlowQuery(sql string, _ time.Duration, _ error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, sql)
+}
+
+func TestSQLiteStore_SlowQueryLog(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	mailbox := newSQLiteMailbox(t, store, "", nil)
+
+	sink := &recordingSlowQuerySink{}
+	store.SetSlowQueryLog(0, sink) // 阈值为 0 时记录所有语句
+	_, err := store.GetMailboxByAddress(t.Context(), mailbox.Address)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, sink.queries)
+	for _, query := range sink.queries {
+		assert.NotContains(t, query, mailbox.Address, "只记录 SQL 模板，不含参数值")
+	}
+	assert.Contains(t, sink.queries[len(sink.queries)-1], "?")
+}
+
+func TestSQLiteStore_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tempmail.db")
+
+	store, err := NewSQLiteStore(path)
+	require.NoError(t, err)
+	mailbox := newSQLiteMailbox(t, store, "", nil)
+	msg := &domain.Message{ID: uuid.NewString(), MailboxID: mailbox.ID, Subject: "persisted", ReceivedAt: time.Now(), CreatedAt: time.Now()}
+	require.NoError(t, store.SaveMessage(t.Context(), msg))
+	require.NoError(t, store.Close())
+
+	reopened, err := NewSQLiteStore(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	got, err := reopened.GetMessage(t.Context(), mailbox.ID, msg.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "persisted", got.Subject)
+}
+
+// sqliteColumns 返回库中每张表的列定义
+func sqliteColumns(t *testing.T, db *sql.DB) map[string][]string {
+	t.Helper()
+	columns := make(map[string][]string)
+	rows, err := db.Query("SELECT m.name, p.name, p.type FROM sqlite_master m JOIN pragma_table_info(m.name) p WHERE m.type = 'table' ORDER BY m.name, p.cid")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var table, column, typ string
+		require.NoError(t, rows.Scan(&table, &column, &typ))
+		columns[table] = append(columns[table], column+" "+typ)
+	}
+	require.NoError(t, rows.Err())
+	return columns
+}
+
+func TestSQLiteMigrationMatchesModels(t *testing.T) {
+	script, err := os.ReadFile(filepath.Join("..", "..", "..", "migrations", "sqlite", "001_initial_schema.up.sql"))
+	require.NoError(t, err)
+
+	migrated, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "migrated.db"))
+	require.NoError(t, err)
+	defer migrated.Close()
+	_, err = migrated.Exec(string(script))
+	require.NoError(t, err)
+
+	store, _ := newSQLiteTestStore(t)
+	sqlDB, err := store.db.DB()
+	require.NoError(t, err)
+
+	// 模型变更后未同步迁移脚本时失败
+	assert.Equal(t, sqliteColumns(t, sqlDB), sqliteColumns(t, migrated))
+}
+
+func TestSQLiteStore_MailboxSummaries(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	userID := uuid.NewString()
+	active := newSQLiteMailbox(t, store, userID, nil)
+	empty := newSQLiteMailbox(t, store, userID, nil)
+	past := time.Now().Add(-time.Hour)
+	newSQLiteMailbox(t, store, userID, &past) // 已过期，不出现在列表中
+	newSQLiteMailbox(t, store, uuid.NewString(), nil)
+
+	base := time.Now().Truncate(time.Second).Add(time.Second) // 晚于邮箱创建时间
+	ids := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		id := uuid.NewString()
+		ids = append(ids, id)
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+			ID: id, MailboxID: active.ID, From: "sender@example.com", Subject: "msg " + string(rune('a'+i)),
+			Size: 100, ReceivedAt: base.Add(time.Duration(i) * time.Minute), CreatedAt: base,
+		}))
+	}
+	require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+		ID: uuid.NewString(), MailboxID: active.ID, Subject: "spam", Size: 50, Quarantined: true,
+		ReceivedAt: base.Add(time.Hour), CreatedAt: base,
+	}))
+	require.NoError(t, store.MarkMessageRead(t.Context(), active.ID, ids[0]))
+	require.NoError(t, store.MarkMessageRead(t.Context(), active.ID, ids[1]))
+	require.NoError(t, store.DeleteMessage(t.Context(), active.ID, ids[1]))
+	require.NoError(t, store.DeleteMessage(t.Context(), active.ID, ids[4]))
+
+	// 逐封邮件统计的结果作为对照
+	messages, err := store.ListMessages(t.Context(), active.ID)
+	require.NoError(t, err)
+	total, unread := 0, 0
+	for _, message := range messages {
+		if message.Quarantined {
+			continue
+		}
+		total++
+		if !message.IsRead {
+			unread++
+		}
+	}
+
+	// 统计实际执行的 SQL 语句数（Scan 走 Row 回调；子查询以 DryRun 方式构建，不计数）
+	var queries atomic.Int64
+	count := func(db *gorm.DB) {
+		if !db.DryRun {
+			queries.Add(1)
+		}
+	}
+	require.NoError(t, store.db.Callback().Query().After("gorm:query").Register("test:count_query", count))
+	require.NoError(t, store.db.Callback().Row().After("gorm:row").Register("test:count_row", count))
+	summaries, err := store.ListMailboxSummariesByUserID(t.Context(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), queries.Load(), "邮箱、数量和最近邮件在一条查询中取出")
+
+	require.Len(t, summaries, 2)
+	byID := map[string]domain.MailboxSummary{}
+	for _, summary := range summaries {
+		byID[summary.ID] = summary
+	}
+
+	got := byID[active.ID]
+	assert.Equal(t, total, got.TotalCount)
+	assert.Equal(t, unread, got.Unread)
+	assert.Equal(t, 3, got.TotalCount)
+	assert.Equal(t, 2, got.Unread)
+	assert.Equal(t, int64(350), got.StorageBytes, "隔离区邮件计入占用")
+	require.NotNil(t, got.LastMessage)
+	assert.Equal(t, ids[3], got.LastMessage.ID, "隔离区邮件不作为预览")
+	assert.Equal(t, "msg d", got.LastMessage.Subject)
+	assert.Equal(t, "sender@example.com", got.LastMessage.From)
+	assert.True(t, base.Add(3*time.Minute).Equal(got.LastMessage.ReceivedAt))
+	assert.Equal(t, got.LastMessage.ReceivedAt, got.LastActivityAt())
+
+	assert.Zero(t, byID[empty.ID].TotalCount)
+	assert.Nil(t, byID[empty.ID].LastMessage)
+	assert.Equal(t, empty.CreatedAt.Unix(), byID[empty.ID].LastActivityAt().Unix())
+}
+
+func TestSQLiteStore_DeleteExpiredMessages(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	first := newSQLiteMailbox(t, store, "", nil)
+	second := newSQLiteMailbox(t, store, "", nil)
+	untouched := newSQLiteMailbox(t, store, "", nil)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	due, later := now.Add(-time.Minute), now.Add(time.Hour)
+	save := func(mailboxID string, expiresAt *time.Time) string {
+		id := uuid.NewString()
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+			ID: id, MailboxID: mailboxID, Subject: "code", ReceivedAt: now, CreatedAt: now, ExpiresAt: expiresAt,
+		}))
+		return id
+	}
+	readDue := save(first.ID, &due)
+	unreadDue := save(first.ID, &due)
+	save(first.ID, &later)
+	save(first.ID, nil)
+	secondDue := save(second.ID, &due)
+	save(untouched.ID, nil)
+	require.NoError(t, store.MarkMessageRead(t.Context(), first.ID, readDue))
+
+	expired, err := store.DeleteExpiredMessages(t.Context(), now)
+	require.NoError(t, err)
+	want := []domain.ExpiredMessages{
+		{MailboxID: first.ID, MessageIDs: []string{readDue, unreadDue}, Unread: 1},
+		{MailboxID: second.ID, MessageIDs: []string{secondDue}, Unread: 1},
+	}
+	for _, batch := range want {
+		slices.Sort(batch.MessageIDs)
+	}
+	slices.SortFunc(want, func(a, b domain.ExpiredMessages) int { return strings.Compare(a.MailboxID, b.MailboxID) })
+	assert.Equal(t, want, expired)
+
+	for _, tc := range []struct {
+		mailbox       *domain.Mailbox
+		total, unread int
+	}{{first, 2, 2}, {second, 0, 0}, {untouched, 1, 1}} {
+		mailbox, err := store.GetMailbox(t.Context(), tc.mailbox.ID)
+		require.NoError(t, err)
+		assert.Equal(t, tc.total, mailbox.TotalCount)
+		assert.Equal(t, tc.unread, mailbox.Unread)
+		messages, err := store.ListMessages(t.Context(), tc.mailbox.ID)
+		require.NoError(t, err)
+		assert.Len(t, messages, tc.total)
+	}
+
+	expired, err = store.DeleteExpiredMessages(t.Context(), now)
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+}
+
+func TestSQLiteStore_MaintenanceJobs(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	t.Run("同类型只能有一个活动任务", func(t *testing.T) {
+		first := &domain.MaintenanceJob{ID: uuid.NewString(), Type: domain.MaintenanceJobRecountMailboxes, Status: domain.MaintenanceJobQueued, CreatedAt: now}
+		require.NoError(t, store.CreateMaintenanceJob(t.Context(), first))
+		dup := &domain.MaintenanceJob{ID: uuid.NewString(), Type: domain.MaintenanceJobRecountMailboxes, Status: domain.MaintenanceJobQueued, CreatedAt: now}
+		assert.ErrorIs(t, store.CreateMaintenanceJob(t.Context(), dup), storage.ErrMaintenanceJobActive)
+		other := &domain.MaintenanceJob{ID: uuid.NewString(), Type: domain.MaintenanceJobBackfillPreviews, Status: domain.MaintenanceJobQueued, CreatedAt: now.Add(time.Second)}
+		require.NoError(t, store.CreateMaintenanceJob(t.Context(), other), "不同类型互不影响")
+
+		first.Status, first.Cursor, first.Processed, first.Updated = domain.MaintenanceJobSucceeded, "mb-9", 9, 2
+		first.FinishedAt = &now
+		require.NoError(t, store.UpdateMaintenanceJob(t.Context(), first))
+		got, err := store.GetMaintenanceJob(t.Context(), first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "mb-9", got.Cursor)
+		assert.Equal(t, int64(2), got.Updated)
+		assert.Nil(t, got.ActiveType, "结束后释放占用")
+		require.NoError(t, store.CreateMaintenanceJob(t.Context(), dup))
+
+		jobs, err := store.ListMaintenanceJobs(t.Context())
+		require.NoError(t, err)
+		require.Len(t, jobs, 3)
+		assert.Equal(t, other.ID, jobs[0].ID, "新任务在前")
+
+		_, err = store.GetMaintenanceJob(t.Context(), "missing")
+		assert.ErrorIs(t, err, storage.ErrMaintenanceJobNotFound)
+		assert.ErrorIs(t, store.UpdateMaintenanceJob(t.Context(), &domain.MaintenanceJob{ID: "missing"}), storage.ErrMaintenanceJobNotFound)
+	})
+
+	t.Run("重新统计邮箱和回填纯文本", func(t *testing.T) {
+		mailbox := newSQLiteMailbox(t, store, "", nil)
+		htmlOnly := &domain.Message{ID: uuid.NewString(), MailboxID: mailbox.ID, HasHTML: true, ReceivedAt: now, CreatedAt: now}
+		require.NoError(t, store.SaveMessage(t.Context(), htmlOnly))
+		require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{ID: uuid.NewString(), MailboxID: mailbox.ID, IsRead: true, ReceivedAt: now, CreatedAt: now}))
+
+		changed, err := store.RecountMailbox(t.Context(), mailbox.ID)
+		require.NoError(t, err)
+		assert.False(t, changed, "增量统计正确时不写")
+
+		require.NoError(t, store.db.Model(&domain.Mailbox{}).Where("id = ?", mailbox.ID).
+			Updates(map[string]interface{}{"total_count": 9, "unread": 4}).Error)
+		changed, err = store.RecountMailbox(t.Context(), mailbox.ID)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		got, err := store.GetMailbox(t.Context(), mailbox.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, got.TotalCount)
+		assert.Equal(t, 1, got.Unread)
+
+		ids, err := store.ListMailboxIDsAfter(t.Context(), "", 100)
+		require.NoError(t, err)
+		assert.Contains(t, ids, mailbox.ID)
+		assert.True(t, slices.IsSorted(ids))
+
+		require.NoError(t, store.SetMessageDerivedText(t.Context(), mailbox.ID, htmlOnly.ID, "generated"))
+		msg, err := store.GetMessage(t.Context(), mailbox.ID, htmlOnly.ID)
+		require.NoError(t, err)
+		assert.True(t, msg.HasText)
+		assert.True(t, msg.TextDerivedFromHTML)
+
+		messages, err := store.ListMessagesAfter(t.Context(), "", 1)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		rest, err := store.ListMessagesAfter(t.Context(), messages[0].ID, 10)
+		require.NoError(t, err)
+		assert.Len(t, rest, 1)
+		count, err := store.CountMessages(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+}
+
+func TestSQLiteStore_AnalyticsBuckets(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	hour := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	merge := func(metric, dimension string, at time.Time, value int64) {
+		t.Helper()
+		require.NoError(t, store.MergeAnalyticsBuckets(t.Context(), []domain.AnalyticsBucket{
+			{Metric: metric, Dimension: dimension, Bucket: at, Value: value},
+		}))
+	}
+	merge(domain.AnalyticsMessagesByDomain, "temp.mail", hour, 3)
+	merge(domain.AnalyticsMessagesByDomain, "temp.mail", hour, 2)
+	merge(domain.AnalyticsMessagesByDomain, "temp.mail", hour.Add(time.Hour), 1)
+	merge(domain.AnalyticsWebSocketPeak, "", hour, 7)
+	merge(domain.AnalyticsWebSocketPeak, "", hour, 4)
+	merge(domain.AnalyticsWebSocketPeak, "", hour.In(time.FixedZone("UTC+8", 8*3600)), 9)
+
+	t.Run("计数累加、峰值取最大值", func(t *testing.T) {
+		buckets, err := store.ListAnalyticsBuckets(t.Context(), domain.AnalyticsMessagesByDomain, hour, hour.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, buckets, 2)
+		assert.Equal(t, int64(5), buckets[0].Value)
+		assert.True(t, hour.Equal(buckets[0].Bucket))
+		assert.Equal(t, int64(1), buckets[1].Value)
+
+		peaks, err := store.ListAnalyticsBuckets(t.Context(), domain.AnalyticsWebSocketPeak, hour, hour.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, peaks, 1, "同一时刻不同时区写入同一个时间桶")
+		assert.Equal(t, int64(9), peaks[0].Value)
+	})
+
+	t.Run("按保留期限删除", func(t *testing.T) {
+		deleted, err := store.DeleteAnalyticsBucketsBefore(t.Context(), hour.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+
+		buckets, err := store.ListAnalyticsBuckets(t.Context(), domain.AnalyticsMessagesByDomain, hour, hour.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, buckets, 1)
+		assert.True(t, hour.Add(time.Hour).Equal(buckets[0].Bucket))
+	})
+}
+
+func TestSQLiteStore_MailFlowCounters(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	minute := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	for range 2 {
+		require.NoError(t, store.MergeMailFlowCounters(t.Context(), []domain.MailFlowCounter{
+			{Kind: domain.MailFlowAccepted, Bucket: minute, Count: 3},
+			{Kind: domain.MailFlowRejected, Dimension: "greylisted", Bucket: minute.Add(time.Minute), Count: 1},
+		}))
+	}
+
+	counters, err := store.ListMailFlowCounters(t.Context(), minute, minute.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, counters, 2)
+	assert.Equal(t, int64(6), counters[0].Count, "同一分钟多次写入累加")
+	assert.True(t, minute.Equal(counters[0].Bucket))
+	assert.Equal(t, "greylisted", counters[1].Dimension)
+
+	deleted, err := store.DeleteMailFlowCountersBefore(t.Context(), minute.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestSQLiteStore_CreateMailboxAddressTaken(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	ctx := t.Context()
+
+	first := &domain.Mailbox{ID: "mb-1", Address: "dup@temp.mail", LocalPart: "dup", Domain: "temp.mail", Token: "t1", CreatedAt: time.Now()}
+	require.NoError(t, store.CreateMailbox(ctx, first))
+
+	second := &domain.Mailbox{ID: "mb-2", Address: "dup@temp.mail", LocalPart: "dup", Domain: "temp.mail", Token: "t2", CreatedAt: time.Now()}
+	require.ErrorIs(t, store.CreateMailbox(ctx, second), storage.ErrAddressTaken)
+
+	got, err := store.GetMailboxByAddress(ctx, "dup@temp.mail")
+	require.NoError(t, err)
+	assert.Equal(t, "mb-1", got.ID)
+	assert.Equal(t, "t1", got.Token)
+
+	t.Run("地址已是别名", func(t *testing.T) {
+		require.NoError(t, store.SaveAlias(ctx, &domain.MailboxAlias{ID: "alias-1", MailboxID: "mb-1", Address: "alias@temp.mail", CreatedAt: time.Now(), IsActive: true}))
+		err := store.CreateMailbox(ctx, &domain.Mailbox{ID: "mb-3", Address: "alias@temp.mail", CreatedAt: time.Now()})
+		require.ErrorIs(t, err, storage.ErrAddressTaken)
+		_, err = store.GetMailbox(ctx, "mb-3")
+		assert.ErrorIs(t, err, ErrMailboxNotFound)
+	})
+
+	t.Run("别名地址已是邮箱", func(t *testing.T) {
+		err := store.SaveAlias(ctx, &domain.MailboxAlias{ID: "alias-2", MailboxID: "mb-1", Address: "dup@temp.mail", CreatedAt: time.Now()})
+		require.ErrorIs(t, err, storage.ErrAddressTaken)
+	})
+}
+
+func TestSQLiteStore_MarkMessageUnread(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	ctx := t.Context()
+	mailbox := newSQLiteMailbox(t, store, "", nil)
+	require.NoError(t, store.SaveMessage(ctx, &domain.Message{
+		ID: "msg-1", MailboxID: mailbox.ID, Subject: "hi", ReceivedAt: time.Now(), CreatedAt: time.Now(),
+	}))
+
+	unread := func() int {
+		got, err := store.GetMailbox(ctx, mailbox.ID)
+		require.NoError(t, err)
+		return got.Unread
+	}
+	require.Equal(t, 1, unread())
+
+	require.NoError(t, store.MarkMessageRead(ctx, mailbox.ID, "msg-1"))
+	assert.Equal(t, 0, unread())
+	require.NoError(t, store.MarkMessageUnread(ctx, mailbox.ID, "msg-1"))
+	assert.Equal(t, 1, unread())
+	message, err := store.GetMessage(ctx, mailbox.ID, "msg-1")
+	require.NoError(t, err)
+	assert.False(t, message.IsRead)
+
+	// 已是未读时与 MarkMessageRead 一致返回未找到，未读数不变
+	assert.ErrorIs(t, store.MarkMessageUnread(ctx, mailbox.ID, "msg-1"), ErrMessageNotFound)
+	assert.Equal(t, 1, unread())
+}
+
+func TestSQLiteStore_DeleteMessagesUpdatesCounts(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	ctx := t.Context()
+	mailbox := newSQLiteMailbox(t, store, "", nil)
+	for _, id := range []string{"msg-1", "msg-2", "msg-3"} {
+		require.NoError(t, store.SaveMessage(ctx, &domain.Message{
+			ID: id, MailboxID: mailbox.ID, Subject: "hi", ReceivedAt: time.Now(), CreatedAt: time.Now(),
+		}))
+	}
+	require.NoError(t, store.MarkMessageRead(ctx, mailbox.ID, "msg-1"))
+
+	counts := func() (int, int) {
+		got, err := store.GetMailbox(ctx, mailbox.ID)
+		require.NoError(t, err)
+		return got.TotalCount, got.Unread
+	}
+
+	require.NoError(t, store.DeleteMessage(ctx, mailbox.ID, "msg-1"))
+	total, unread := counts()
+	assert.Equal(t, []int{2, 2}, []int{total, unread}, "删除已读邮件不扣减未读数")
+	require.NoError(t, store.DeleteMessage(ctx, mailbox.ID, "msg-2"))
+	total, unread = counts()
+	assert.Equal(t, []int{1, 1}, []int{total, unread})
+	assert.ErrorIs(t, store.DeleteMessage(ctx, mailbox.ID, "msg-2"), ErrMessageNotFound)
+
+	deleted, err := store.DeleteAllMessages(ctx, mailbox.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	total, unread = counts()
+	assert.Equal(t, []int{0, 0}, []int{total, unread})
+}
+
+func TestSQLiteStore_ListMessagesPage(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	ctx := t.Context()
+	mailbox := newSQLiteMailbox(t, store, "", nil)
+	base := time.Now().UTC().Truncate(time.Second)
+	for i, msg := range []struct{ id, subject, from string }{
+		{"msg-0", "banana", "Carol@example.com"}, {"msg-1", "Apple", "bob@example.com"},
+		{"msg-2", "cherry", "alice@example.com"}, {"msg-3", "apple pie", "Bob@example.com"},
+	} {
+		require.NoError(t, store.SaveMessage(ctx, &domain.Message{
+			ID: msg.id, MailboxID: mailbox.ID, Subject: msg.subject, From: msg.from,
+			ReceivedAt: base.Add(time.Duration(i) * time.Minute), CreatedAt: base,
+		}))
+	}
+	require.NoError(t, store.MarkMessageRead(ctx, mailbox.ID, "msg-1"))
+	require.NoError(t, store.SaveMessage(ctx, &domain.Message{
+		ID: "msg-q", MailboxID: mailbox.ID, Subject: "quarantined", ReceivedAt: base, CreatedAt: base, Quarantined: true,
+	}))
+
+	ids := func(query domain.MessageListQuery) ([]string, int) {
+		query.MailboxID = mailbox.ID
+		page, err := store.ListMessagesPage(ctx, query)
+		require.NoError(t, err)
+		result := make([]string, 0, len(page.Messages))
+		for _, message := range page.Messages {
+			result = append(result, message.ID)
+		}
+		return result, page.Total
+	}
+
+	got, total := ids(domain.MessageListQuery{Limit: 2})
+	assert.Equal(t, []string{"msg-3", "msg-2"}, got)
+	assert.Equal(t, 4, total)
+	got, _ = ids(domain.MessageListQuery{Limit: 2, Offset: 2})
+	assert.Equal(t, []string{"msg-1", "msg-0"}, got)
+	got, _ = ids(domain.MessageListQuery{Sort: domain.MessageSortSubject, Ascending: true})
+	assert.Equal(t, []string{"msg-1", "msg-3", "msg-0", "msg-2"}, got)
+	got, _ = ids(domain.MessageListQuery{Sort: domain.MessageSortFrom})
+	assert.Equal(t, []string{"msg-0", "msg-3", "msg-1", "msg-2"}, got, "相同发件人按入库顺序倒序")
+	got, total = ids(domain.MessageListQuery{UnreadOnly: true, Limit: 10})
+	assert.Equal(t, []string{"msg-3", "msg-2", "msg-0"}, got)
+	assert.Equal(t, 3, total)
+	got, total = ids(domain.MessageListQuery{Quarantined: true})
+	assert.Equal(t, []string{"msg-q"}, got)
+	assert.Equal(t, 1, total)
+}
+
+func TestSQLiteStore_SentMessages(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	userID := uuid.NewString()
+	first := newSQLiteMailbox(t, store, userID, nil)
+	second := newSQLiteMailbox(t, store, userID, nil)
+
+	base := time.Now().UTC().Truncate(time.Second)
+	for i, mailbox := range []*domain.Mailbox{first, first, second} {
+		require.NoError(t, store.SaveSentMessage(t.Context(), &domain.SentMessage{
+			ID: uuid.NewString(), MailboxID: mailbox.ID, UserID: userID, From: mailbox.Address,
+			To: []string{"<a@example.com>"}, Subject: "msg " + string(rune('a'+i)), SentAt: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	list, err := store.ListSentMessages(t.Context(), first.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "msg b", list[0].Subject) // 新邮件在前
+	assert.Equal(t, []string{"<a@example.com>"}, list[0].To)
+
+	usage, err := store.CountSentMessages(t.Context(), domain.SentMessageFilter{UserID: userID, Since: base})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Count) // 恰好在 Since 发出的不计入
+	assert.True(t, usage.OldestAt.Equal(base.Add(time.Hour)), usage.OldestAt)
+
+	require.NoError(t, store.DeleteMailbox(t.Context(), first.ID))
+	usage, err = store.CountSentMessages(t.Context(), domain.SentMessageFilter{UserID: userID, Since: base.Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.Count)
+}
+
+func TestSQLiteStore_DomainWhitelist(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	ctx := t.Context()
+	userDomain := &domain.UserDomain{ID: uuid.NewString(), UserID: uuid.NewString(), Domain: "list.example", Mode: domain.DomainModeWhitelist}
+	require.NoError(t, store.SaveUserDomain(ctx, userDomain))
+
+	for _, localPart := range []string{"sales", "info"} {
+		require.NoError(t, store.AddDomainWhitelistEntry(ctx, &domain.DomainWhitelistEntry{
+			ID: uuid.NewString(), DomainID: userDomain.ID, LocalPart: localPart, CreatedAt: time.Now(),
+		}))
+	}
+	err := store.AddDomainWhitelistEntry(ctx, &domain.DomainWhitelistEntry{ID: uuid.NewString(), DomainID: userDomain.ID, LocalPart: "info"})
+	assert.ErrorIs(t, err, storage.ErrWhitelistEntryExists)
+
+	entries, err := store.ListDomainWhitelist(ctx, userDomain.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "info", entries[0].LocalPart) // 按前缀排序
+
+	ok, err := store.IsLocalPartWhitelisted(ctx, userDomain.ID, "sales")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = store.IsLocalPartWhitelisted(ctx, userDomain.ID, "other")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, store.DeleteDomainWhitelistEntry(ctx, "other-domain", entries[0].ID), storage.ErrWhitelistEntryNotFound)
+	require.NoError(t, store.DeleteDomainWhitelistEntry(ctx, userDomain.ID, entries[0].ID))
+
+	// 删除域名时一并删除白名单
+	require.NoError(t, store.DeleteUserDomain(ctx, userDomain.ID))
+	entries, err = store.ListDomainWhitelist(ctx, userDomain.ID)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSQLiteStore_ReservedPrefixes(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	ctx := t.Context()
+	userID := uuid.NewString()
+	expiresAt := time.Now().Add(time.Hour).UTC()
+
+	require.NoError(t, store.CreateReservedPrefix(ctx, &domain.ReservedPrefix{ID: "rp-1", LocalPart: "billing", CreatedAt: time.Now()}))
+	require.NoError(t, store.CreateReservedPrefix(ctx, &domain.ReservedPrefix{
+		ID: "rp-2", Domain: "temp.mail", LocalPart: "vip", UserID: &userID, ExpiresAt: &expiresAt, CreatedAt: time.Now(),
+	}))
+	require.NoError(t, store.CreateReservedPrefix(ctx, &domain.ReservedPrefix{ID: "rp-3", Domain: "other.mail", LocalPart: "billing", CreatedAt: time.Now()}))
+	assert.ErrorIs(t, store.CreateReservedPrefix(ctx, &domain.ReservedPrefix{ID: "rp-4", LocalPart: "billing"}), storage.ErrReservedPrefixExists)
+
+	found, err := store.FindReservedPrefixes(ctx, "temp.mail", "billing")
+	require.NoError(t, err)
+	require.Len(t, found, 1, "所有系统域名的条目也会匹配")
+	assert.Equal(t, "rp-1", found[0].ID)
+
+	owned, err := store.ListReservedPrefixes(ctx, &userID)
+	require.NoError(t, err)
+	require.Len(t, owned, 1)
+	assert.Equal(t, "vip", owned[0].LocalPart)
+	all, err := store.ListReservedPrefixes(ctx, nil)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	require.NoError(t, store.DeleteReservedPrefix(ctx, "rp-2"))
+	assert.ErrorIs(t, store.DeleteReservedPrefix(ctx, "rp-2"), storage.ErrReservedPrefixNotFound)
+}
+
+func TestSQLiteStore_CanceledContext(t *testing.T) {
+	store, _ := newSQLiteTestStore(t)
+	user := &domain.User{ID: uuid.NewString(), Email: "ctx@corp.example", Username: "ctx-user", IsActive: true}
+	require.NoError(t, store.CreateUser(t.Context(), user))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	// 请求取消后查询不再执行
+	_, err := store.GetUserByID(ctx, user.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, store.UpdateLastLogin(ctx, user.ID), context.Canceled)
+
+	found, err := store.GetUserByID(t.Context(), user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found.LastLoginAt)
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"tempmail/backend/internal/domain"
+)
+
+// GetMessageStats 统计邮件收件情况
+//
+// 所有查询都限定在 [Since, Until) 窗口内，走 (mailbox_id, received_at) 复合索引；
+// 小时桶按 UTC 截断，不受数据库会话时区和夏令时影响。
+func (s *Store) GetMessageStats(ctx context.Context, query domain.MessageStatsQuery) (*domain.MessageStats, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	stats := &domain.MessageStats{
+		Since:      query.Since,
+		Until:      query.Until,
+		Hourly:     []domain.HourlyCount{},
+		TopSenders: []domain.SenderCount{},
+		ByMailbox:  make(map[string]int),
+	}
+	if len(query.MailboxIDs) == 0 {
+		return stats, nil
+	}
+
+	window := func() *gorm.DB {
+		return db.Model(&domain.Message{}).
+			Where("mailbox_id IN ? AND received_at >= ? AND received_at < ?", query.MailboxIDs, query.Since, query.Until)
+	}
+
+	// 汇总
+	var totals struct {
+		Total   int
+		Unread  int
+		AvgSize float64
+	}
+	if err := window().
+		Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN is_read THEN 0 ELSE 1 END), 0) AS unread, COALESCE(AVG(size), 0) AS avg_size").
+		Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate message stats: %w", err)
+	}
+	stats.Total = totals.Total
+	stats.Unread = totals.Unread
+	stats.AverageSize = int64(totals.AvgSize)
+	if stats.Total == 0 {
+		return stats, nil
+	}
+
+	// 每小时收件数
+	var hourly []struct {
+		Hour  hourBucket
+		Count int
+	}
+	if err := window().
+		Select(s.hourBucketExpr() + " AS hour, COUNT(*) AS count").
+		Group("hour").
+		Order("hour").
+		Scan(&hourly).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate hourly stats: %w", err)
+	}
+	for _, row := range hourly {
+		stats.Hourly = append(stats.Hourly, domain.HourlyCount{Hour: row.Hour.t.UTC(), Count: row.Count})
+	}
+
+	// 发件人排行
+	senderQuery := window().
+		Select("LOWER(?) AS sender, COUNT(*) AS count", clause.Column{Name: "from"}).
+		Group("sender").
+		Order("count DESC, sender")
+	if query.TopSenders > 0 {
+		senderQuery = senderQuery.Limit(query.TopSenders)
+	}
+	if err := senderQuery.Scan(&stats.TopSenders).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate sender stats: %w", err)
+	}
+
+	// 按邮箱统计
+	var byMailbox []struct {
+		MailboxID string
+		Count     int
+	}
+	if err := window().
+		Select("mailbox_id, COUNT(*) AS count").
+		Group("mailbox_id").
+		Scan(&byMailbox).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate mailbox stats: %w", err)
+	}
+	for _, row := range byMailbox {
+		stats.ByMailbox[row.MailboxID] = row.Count
+	}
+
+	return stats, nil
+}
+
+// hourBucketExpr 按 UTC 小时截断接收时间的 SQL 表达式
+func (s *Store) hourBucketExpr() string {
+	switch s.db.Dialector.Name() {
+	case "mysql":
+		// MySQL 的 DATETIME 不带时区，写入时已统一为 UTC
+		return "TIMESTAMP(DATE_FORMAT(received_at, '%Y-%m-%d %H:00:00'))"
+	case "sqlite":
+		// SQLite 以文本保存时间，strftime 会按偏移量换算到 UTC
+		return "strftime('%Y-%m-%d %H:00:00', received_at)"
+	}
+	return "date_trunc('hour', received_at AT TIME ZONE 'UTC')"
+}
+
+// hourBucket 小时桶扫描结果，兼容 SQLite 以文本返回的时间
+type hourBucket struct {
+	t time.Time
+}
+
+// Value 实现 driver.Valuer（仅用于满足 GORM 的字段解析）
+func (h hourBucket) Value() (driver.Value, error) {
+	return h.t, nil
+}
+
+// Scan 实现 sql.Scanner
+func (h *hourBucket) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		h.t = v
+		return nil
+	case []byte:
+		return h.parse(string(v))
+	case string:
+		return h.parse(v)
+	}
+	return fmt.Errorf("unsupported hour bucket value %T", value)
+}
+
+func (h *hourBucket) parse(value string) error {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.UTC)
+	if err != nil {
+		return fmt.Errorf("invalid hour bucket %q: %w", value, err)
+	}
+	h.t = t
+	return nil
+}
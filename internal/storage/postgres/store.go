@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -9,18 +10,22 @@ import (
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
 )
 
 var (
-	ErrMailboxNotFound = fmt.Errorf("mailbox not found")
+	ErrMailboxNotFound = storage.ErrMailboxNotFound
 	ErrMessageNotFound = fmt.Errorf("message not found")
 	ErrUserNotFound    = fmt.Errorf("user not found")
 	ErrEmailExists     = fmt.Errorf("email already exists")
 	ErrAliasNotFound   = fmt.Errorf("alias not found")
 	ErrAliasExists     = fmt.Errorf("alias already exists")
+
+	ErrSystemDomainNotFound = fmt.Errorf("system domain not found")
 )
 
 // Store PostgreSQL 存储实现
@@ -40,16 +45,8 @@ func NewMySQLStore(dsn string) (*Store, error) {
 
 // NewStoreWithDialector 使用指定的GORM dialector创建存储实例
 func NewStoreWithDialector(dialector gorm.Dialector) (*Store, error) {
-	// 配置 GORM
-	config := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent), // 静默模式
-		NowFunc: func() time.Time {
-			return time.Now().UTC()
-		},
-	}
-
 	// 连接数据库
-	db, err := gorm.Open(dialector, config)
+	db, err := gorm.Open(dialector, gormConfig())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -73,12 +70,23 @@ func NewStoreWithDialector(dialector gorm.Dialector) (*Store, error) {
 	return store, nil
 }
 
+// gormConfig GORM 通用配置
+func gormConfig() *gorm.Config {
+	return &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent), // 静默模式
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
 // migrate 自动迁移数据库表结构
 func (s *Store) migrate() error {
 	return s.db.AutoMigrate(
 		&domain.User{},
 		&domain.Mailbox{},
 		&domain.Message{},
+		&domain.MessageSequence{},
 		&domain.MailboxAlias{},
 		&domain.SystemDomain{},
 		&domain.UserDomain{},
@@ -88,20 +96,75 @@ func (s *Store) migrate() error {
 		&domain.WebhookDelivery{},
 		&domain.Tag{},
 		&domain.MessageTag{},
+		&domain.Organization{},
+		&domain.OrgMember{},
+		&domain.OrgInvite{},
+		&domain.DistributionList{},
+		&domain.DistributionListDelivery{},
+		&domain.MessageRedaction{},
+		&domain.AbuseReport{},
+		&domain.MessageShare{},
+		&domain.SentMessage{},
+		&domain.DomainWhitelistEntry{},
+		&domain.ReservedPrefix{},
+		&domain.OAuthIdentity{},
+		&domain.UserSession{},
+		&domain.AuditLog{},
+		&domain.OutboxEvent{},
+		&domain.MailboxForward{},
+		&domain.ForwardDelivery{},
+		&domain.SearchDocument{},
+		&domain.MaintenanceJob{},
+		&domain.AnalyticsBucket{},
+		&domain.MailFlowCounter{},
 	)
 }
 
 // ========== Mailbox Repository ==========
 
+// CreateMailbox 创建新邮箱（INSERT ... ON CONFLICT DO NOTHING，按影响行数判断冲突），
+// 地址已被邮箱或别名占用时返回 storage.ErrAddressTaken
+//
+// 邮箱和别名不在同一张表，插入提交后再确认没有同时创建的同地址别名，有则撤销本次插入；
+// 别名一侧同样先插入再确认，两边并发时至少一方能看到另一方。
+func (s *Store) CreateMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(mailbox)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrAddressTaken
+	}
+
+	var aliases int64
+	if err := db.Model(&domain.MailboxAlias{}).Where("address = ?", mailbox.Address).Count(&aliases).Error; err != nil {
+		return err
+	}
+	if aliases > 0 {
+		if err := db.Where("id = ?", mailbox.ID).Delete(&domain.Mailbox{}).Error; err != nil {
+			return err
+		}
+		return storage.ErrAddressTaken
+	}
+	return nil
+}
+
 // SaveMailbox 保存邮箱信息
-func (s *Store) SaveMailbox(mailbox *domain.Mailbox) error {
-	return s.db.Save(mailbox).Error
+func (s *Store) SaveMailbox(ctx context.Context, mailbox *domain.Mailbox) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(mailbox).Error
 }
 
 // GetMailbox 根据 ID 获取邮箱
-func (s *Store) GetMailbox(id string) (*domain.Mailbox, error) {
+func (s *Store) GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var mailbox domain.Mailbox
-	err := s.db.Where("id = ? AND (expires_at IS NULL OR expires_at > ?)", id, time.Now()).First(&mailbox).Error
+	err := db.Where("id = ? AND (expires_at IS NULL OR expires_at > ?)", id, time.Now()).First(&mailbox).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrMailboxNotFound
@@ -112,9 +175,11 @@ func (s *Store) GetMailbox(id string) (*domain.Mailbox, error) {
 }
 
 // GetMailboxByAddress 根据完整地址获取邮箱
-func (s *Store) GetMailboxByAddress(address string) (*domain.Mailbox, error) {
+func (s *Store) GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var mailbox domain.Mailbox
-	err := s.db.Where("address = ? AND (expires_at IS NULL OR expires_at > ?)", address, time.Now()).First(&mailbox).Error
+	err := db.Where("address = ? AND (expires_at IS NULL OR expires_at > ?)", address, time.Now()).First(&mailbox).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrMailboxNotFound
@@ -125,44 +190,230 @@ func (s *Store) GetMailboxByAddress(address string) (*domain.Mailbox, error) {
 }
 
 // ListMailboxes 返回全部邮箱的快照
-func (s *Store) ListMailboxes() []domain.Mailbox {
+func (s *Store) ListMailboxes(ctx context.Context) []domain.Mailbox {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var mailboxes []domain.Mailbox
-	s.db.Where("expires_at IS NULL OR expires_at > ?", time.Now()).Find(&mailboxes)
+	db.Where("expires_at IS NULL OR expires_at > ?", time.Now()).Find(&mailboxes)
 	return mailboxes
 }
 
 // ListMailboxesByUserID 返回指定用户的全部邮箱
-func (s *Store) ListMailboxesByUserID(userID string) []domain.Mailbox {
+func (s *Store) ListMailboxesByUserID(ctx context.Context, userID string) []domain.Mailbox {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var mailboxes []domain.Mailbox
-	s.db.Where("user_id = ? AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).Find(&mailboxes)
+	db.Where("user_id = ? AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).Find(&mailboxes)
+	return mailboxes
+}
+
+// GetMailboxesByAddresses 批量按地址查询未过期的邮箱，不存在的地址直接忽略
+func (s *Store) GetMailboxesByAddresses(ctx context.Context, addresses []string) ([]domain.Mailbox, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	if len(addresses) == 0 {
+		return []domain.Mailbox{}, nil
+	}
+	var mailboxes []domain.Mailbox
+	err := db.Where("address IN ? AND (expires_at IS NULL OR expires_at > ?)", addresses, time.Now()).Find(&mailboxes).Error
+	if err != nil {
+		return nil, err
+	}
+	return mailboxes, nil
+}
+
+// ListMailboxesByOrgID 根据组织ID获取邮箱列表
+func (s *Store) ListMailboxesByOrgID(ctx context.Context, orgID string) []domain.Mailbox {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var mailboxes []domain.Mailbox
+	db.Where("org_id = ? AND (expires_at IS NULL OR expires_at > ?)", orgID, time.Now()).Find(&mailboxes)
 	return mailboxes
 }
 
 // DeleteMailbox 删除指定邮箱
-func (s *Store) DeleteMailbox(id string) error {
+func (s *Store) DeleteMailbox(ctx context.Context, id string) error {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	// 使用事务删除邮箱及其相关数据
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 删除邮箱的邮件
-		if err := tx.Where("mailbox_id = ?", id).Delete(&domain.Message{}).Error; err != nil {
-			return err
-		}
+	return db.Transaction(func(tx *gorm.DB) error {
+		return deleteMailboxesTx(tx, []string{id})
+	})
+}
 
-		// 删除邮箱的别名
-		if err := tx.Where("mailbox_id = ?", id).Delete(&domain.MailboxAlias{}).Error; err != nil {
-			return err
-		}
+// deleteMailboxesTx 在事务内删除邮箱及其邮件、邮件标签和别名
+func deleteMailboxesTx(tx *gorm.DB, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
 
-		// 删除邮箱
-		return tx.Where("id = ?", id).Delete(&domain.Mailbox{}).Error
-	})
+	// 删除邮件标签关联（message_tags 没有外键级联）
+	messageIDs := tx.Model(&domain.Message{}).Select("id").Where("mailbox_id IN ?", ids)
+	if err := tx.Where("message_id IN (?)", messageIDs).Delete(&domain.MessageTag{}).Error; err != nil {
+		return err
+	}
+
+	// 删除脱敏副本
+	if err := tx.Where("mailbox_id IN ?", ids).Delete(&domain.MessageRedaction{}).Error; err != nil {
+		return err
+	}
+
+	// 删除分享链接
+	if err := tx.Where("mailbox_id IN ?", ids).Delete(&domain.MessageShare{}).Error; err != nil {
+		return err
+	}
+
+	// 删除已发送邮件
+	if err := tx.Where("mailbox_id IN ?", ids).Delete(&domain.SentMessage{}).Error; err != nil {
+		return err
+	}
+
+	// 删除转发地址和转发投递记录
+	if err := tx.Where("mailbox_id IN ?", ids).Delete(&domain.MailboxForward{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("mailbox_id IN ?", ids).Delete(&domain.ForwardDelivery{}).Error; err != nil {
+		return err
+	}
+
+	// 删除全文索引文档和邮件
+	if err := tx.Where("mailbox_id IN ?", ids).Delete(&domain.SearchDocument{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("mailbox_id IN ?", ids).Delete(&domain.Message{}).Error; err != nil {
+		return err
+	}
+
+	// 删除别名
+	if err := tx.Where("mailbox_id IN ?", ids).Delete(&domain.MailboxAlias{}).Error; err != nil {
+		return err
+	}
+
+	// 删除邮件序号计数
+	if err := tx.Where("mailbox_id IN ?", ids).Delete(&domain.MessageSequence{}).Error; err != nil {
+		return err
+	}
+
+	// 删除邮箱 Webhook 及其投递记录
+	webhookIDs := tx.Model(&domain.Webhook{}).Select("id").Where("owner_type = ? AND owner_id IN ?", domain.WebhookOwnerMailbox, ids)
+	if err := tx.Where("webhook_id IN (?)", webhookIDs).Delete(&domain.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("owner_type = ? AND owner_id IN ?", domain.WebhookOwnerMailbox, ids).Delete(&domain.Webhook{}).Error; err != nil {
+		return err
+	}
+
+	// 删除邮箱
+	return tx.Where("id IN ?", ids).Delete(&domain.Mailbox{}).Error
+}
+
+// ListExpiredMailboxes 列出在指定时间已过期的邮箱
+func (s *Store) ListExpiredMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var mailboxes []domain.Mailbox
+	if err := db.Where("expires_at IS NOT NULL AND expires_at <= ?", now).Find(&mailboxes).Error; err != nil {
+		return nil, err
+	}
+	return mailboxes, nil
+}
+
+// TouchMailbox 记录访问时间，并恢复闲置时被缩短的有效期
+//
+// 赋值顺序有意为之：MySQL 按从左到右使用已更新的值，必须先恢复 expires_at 再清空闲置标记。
+func (s *Store) TouchMailbox(ctx context.Context, mailboxID string, at time.Time) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result := db.Exec(`UPDATE mailboxes SET
+		expires_at = CASE WHEN idle_shortened THEN idle_original_expires_at ELSE expires_at END,
+		last_accessed_at = ?, idle_since = NULL, idle_shortened = ?, idle_original_expires_at = NULL
+		WHERE id = ?`, at, false, mailboxID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMailboxNotFound
+	}
+	return nil
+}
+
+// ListIdleMailboxes 列出 before 之后未被访问、尚未标记闲置且未过期的用户邮箱
+func (s *Store) ListIdleMailboxes(ctx context.Context, before, now time.Time) ([]domain.Mailbox, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var mailboxes []domain.Mailbox
+	err := db.Where("user_id IS NOT NULL AND idle_since IS NULL").
+		Where("COALESCE(last_accessed_at, created_at) < ?", before).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Find(&mailboxes).Error
+	if err != nil {
+		return nil, err
+	}
+	return mailboxes, nil
+}
+
+// ListPublicMailboxes 列出未过期的公开收件箱
+func (s *Store) ListPublicMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var mailboxes []domain.Mailbox
+	err := db.Where("is_public = ?", true).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("address").
+		Find(&mailboxes).Error
+	if err != nil {
+		return nil, err
+	}
+	return mailboxes, nil
+}
+
+// MarkMailboxIdle 标记邮箱闲置，shortenTo 非空时缩短有效期
+func (s *Store) MarkMailboxIdle(ctx context.Context, mailboxID string, at time.Time, shortenTo *time.Time) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	var result *gorm.DB
+	if shortenTo == nil {
+		result = db.Exec("UPDATE mailboxes SET idle_since = ? WHERE id = ?", at, mailboxID)
+	} else {
+		// 先保存原始过期时间再覆盖（已缩短过的保留第一次的原值）
+		result = db.Exec(`UPDATE mailboxes SET
+			idle_original_expires_at = CASE WHEN idle_shortened THEN idle_original_expires_at ELSE expires_at END,
+			expires_at = ?, idle_shortened = ?, idle_since = ?
+			WHERE id = ?`, *shortenTo, true, at, mailboxID)
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMailboxNotFound
+	}
+	return nil
+}
+
+// ExtendMailbox 设置未过期邮箱新的过期时间并清除闲置状态
+func (s *Store) ExtendMailbox(ctx context.Context, mailboxID string, expiresAt time.Time) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result := db.Exec(`UPDATE mailboxes SET
+		expires_at = ?, idle_since = NULL, idle_shortened = ?, idle_original_expires_at = NULL
+		WHERE id = ? AND (expires_at IS NULL OR expires_at > ?)`, expiresAt, false, mailboxID, time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMailboxNotFound
+	}
+	return nil
 }
 
 // DeleteExpiredMailboxes 删除所有过期的邮箱，返回删除数量
-func (s *Store) DeleteExpiredMailboxes() (int, error) {
+func (s *Store) DeleteExpiredMailboxes(ctx context.Context) (int, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var count int64
 
 	// 使用事务删除过期邮箱
-	err := s.db.Transaction(func(tx *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
 		// 查找过期的邮箱
 		var expiredMailboxes []domain.Mailbox
 		if err := tx.Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now()).Find(&expiredMailboxes).Error; err != nil {
@@ -174,22 +425,11 @@ func (s *Store) DeleteExpiredMailboxes() (int, error) {
 			return nil
 		}
 
-		// 删除过期邮箱的邮件
-		for _, mb := range expiredMailboxes {
-			if err := tx.Where("mailbox_id = ?", mb.ID).Delete(&domain.Message{}).Error; err != nil {
-				return err
-			}
-		}
-
-		// 删除过期邮箱的别名
+		ids := make([]string, 0, len(expiredMailboxes))
 		for _, mb := range expiredMailboxes {
-			if err := tx.Where("mailbox_id = ?", mb.ID).Delete(&domain.MailboxAlias{}).Error; err != nil {
-				return err
-			}
+			ids = append(ids, mb.ID)
 		}
-
-		// 删除过期邮箱
-		return tx.Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now()).Delete(&domain.Mailbox{}).Error
+		return deleteMailboxesTx(tx, ids)
 	})
 
 	return int(count), err
@@ -197,40 +437,211 @@ func (s *Store) DeleteExpiredMailboxes() (int, error) {
 
 // ========== Message Repository ==========
 
-// SaveMessage 保存邮件信息
-func (s *Store) SaveMessage(message *domain.Message) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 保存邮件
-		if err := tx.Save(message).Error; err != nil {
+// SaveMessage 保存邮件信息，在同一事务内分配邮箱内序号并更新邮箱统计
+func (s *Store) SaveMessage(ctx context.Context, message *domain.Message) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 先更新邮箱统计：行锁让同一邮箱的并发写入串行分配序号
+		unread := 0
+		if !message.IsRead {
+			unread = 1
+		}
+		if err := incrementMailboxCounts(tx, message.MailboxID, 1, unread); err != nil {
 			return err
 		}
 
-		// 更新邮箱统计
-		var mailbox domain.Mailbox
-		if err := tx.Where("id = ?", message.MailboxID).First(&mailbox).Error; err != nil {
-			return err
+		// 已带序号（快照迁移、重复保存）时保留，并推进计数
+		if message.Seq > 0 {
+			if err := advanceMessageSeq(tx, message.MailboxID, message.Seq); err != nil {
+				return err
+			}
+		} else {
+			last, err := reserveMessageSeqs(tx, message.MailboxID, 1)
+			if err != nil {
+				return err
+			}
+			message.Seq = last
 		}
 
-		mailbox.TotalCount++
-		if !message.IsRead {
-			mailbox.Unread++
+		// 保存邮件
+		return tx.Save(message).Error
+	})
+}
+
+// incrementMailboxCounts 累加邮箱的邮件总数和未读数
+func incrementMailboxCounts(tx *gorm.DB, mailboxID string, total, unread int) error {
+	result := tx.Model(&domain.Mailbox{}).Where("id = ?", mailboxID).Updates(map[string]interface{}{
+		"total_count": gorm.Expr("total_count + ?", total),
+		"unread":      gorm.Expr("unread + ?", unread),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMailboxNotFound
+	}
+	return nil
+}
+
+// reserveMessageSeqs 为邮箱预留 count 个连续序号，返回其中最大的一个
+//
+// 调用方须已在同一事务内锁住邮箱行（见 incrementMailboxCounts），计数行不存在时的插入不会并发。
+func reserveMessageSeqs(tx *gorm.DB, mailboxID string, count int) (int64, error) {
+	result := tx.Model(&domain.MessageSequence{}).Where("mailbox_id = ?", mailboxID).
+		Update("last_seq", gorm.Expr("last_seq + ?", count))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := tx.Create(&domain.MessageSequence{MailboxID: mailboxID, LastSeq: int64(count)}).Error; err != nil {
+			return 0, err
 		}
+		return int64(count), nil
+	}
+
+	var sequence domain.MessageSequence
+	if err := tx.Where("mailbox_id = ?", mailboxID).First(&sequence).Error; err != nil {
+		return 0, err
+	}
+	return sequence.LastSeq, nil
+}
+
+// advanceMessageSeq 保留已有序号时把计数推进到不小于该序号
+func advanceMessageSeq(tx *gorm.DB, mailboxID string, seq int64) error {
+	result := tx.Model(&domain.MessageSequence{}).Where("mailbox_id = ?", mailboxID).
+		Update("last_seq", gorm.Expr("CASE WHEN last_seq < ? THEN ? ELSE last_seq END", seq, seq))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return tx.Create(&domain.MessageSequence{MailboxID: mailboxID, LastSeq: seq}).Error
+	}
+	return nil
+}
 
-		return tx.Save(&mailbox).Error
+// SaveMessages 在同一事务内批量插入邮件，并按邮箱累加统计
+func (s *Store) SaveMessages(ctx context.Context, messages []*domain.Message) error {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	if len(messages) == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		return saveMessages(tx, messages)
 	})
 }
 
+// saveMessages 在事务 tx 内批量插入邮件并按邮箱累加统计
+func saveMessages(tx *gorm.DB, messages []*domain.Message) error {
+	type counts struct {
+		total, unread int
+		next          int64 // 下一个待分配的序号
+	}
+	perMailbox := make(map[string]*counts)
+	order := make([]string, 0)
+	for _, message := range messages {
+		c, ok := perMailbox[message.MailboxID]
+		if !ok {
+			c = &counts{}
+			perMailbox[message.MailboxID] = c
+			order = append(order, message.MailboxID)
+		}
+		c.total++
+		if !message.IsRead {
+			c.unread++
+		}
+	}
+
+	// 先更新邮箱统计并预留序号，再按输入顺序分配
+	for _, mailboxID := range order {
+		c := perMailbox[mailboxID]
+		if err := incrementMailboxCounts(tx, mailboxID, c.total, c.unread); err != nil {
+			return err
+		}
+		last, err := reserveMessageSeqs(tx, mailboxID, c.total)
+		if err != nil {
+			return err
+		}
+		c.next = last - int64(c.total) + 1
+	}
+	for _, message := range messages {
+		c := perMailbox[message.MailboxID]
+		message.Seq = c.next
+		c.next++
+	}
+	return tx.CreateInBatches(messages, 100).Error
+}
+
 // ListMessages 返回某个邮箱下的全部邮件
-func (s *Store) ListMessages(mailboxID string) ([]domain.Message, error) {
+func (s *Store) ListMessages(ctx context.Context, mailboxID string) ([]domain.Message, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var messages []domain.Message
-	err := s.db.Where("mailbox_id = ?", mailboxID).Order("created_at DESC").Find(&messages).Error
+	err := db.Where("mailbox_id = ?", mailboxID).Order("seq DESC, created_at DESC").Find(&messages).Error
 	return messages, err
 }
 
+// ListMessagesPage 按条件过滤、排序并分页列出邮件（排序与 domain.SortMessages 一致）
+func (s *Store) ListMessagesPage(ctx context.Context, q domain.MessageListQuery) (*domain.MessagePage, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	query := db.Model(&domain.Message{}).Where("mailbox_id = ? AND quarantined = ?", q.MailboxID, q.Quarantined)
+	if q.UnreadOnly {
+		query = query.Where("is_read = ?", false)
+	}
+	if q.IsSpam != nil {
+		query = query.Where("is_spam = ?", *q.IsSpam)
+	}
+	if len(q.Headers) > 0 {
+		var err error
+		if query, err = s.whereHeaders(query, q.Headers); err != nil {
+			return nil, err
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	direction := "DESC"
+	if q.Ascending {
+		direction = "ASC"
+	}
+	var key clause.Expr
+	switch q.Sort {
+	case domain.MessageSortSubject:
+		key = clause.Expr{SQL: "LOWER(subject)"}
+	case domain.MessageSortFrom:
+		// from 是保留字，通过 clause.Column 按方言加引号
+		key = clause.Expr{SQL: "LOWER(?)", Vars: []interface{}{clause.Column{Name: "from"}}}
+	default:
+		key = clause.Expr{SQL: "received_at"}
+	}
+	order := clause.OrderBy{Expression: clause.Expr{
+		SQL:  fmt.Sprintf("? %[1]s, seq %[1]s, created_at %[1]s, id %[1]s", direction),
+		Vars: []interface{}{key},
+	}}
+	query = query.Order(order).Offset(max(q.Offset, 0))
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+
+	var messages []domain.Message
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	return &domain.MessagePage{Messages: messages, Total: int(total)}, nil
+}
+
 // GetMessage 获取单封邮件
-func (s *Store) GetMessage(mailboxID, messageID string) (*domain.Message, error) {
+func (s *Store) GetMessage(ctx context.Context, mailboxID, messageID string) (*domain.Message, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var message domain.Message
-	err := s.db.Where("id = ? AND mailbox_id = ?", messageID, mailboxID).First(&message).Error
+	err := db.Where("id = ? AND mailbox_id = ?", messageID, mailboxID).First(&message).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrMessageNotFound
@@ -241,8 +652,10 @@ func (s *Store) GetMessage(mailboxID, messageID string) (*domain.Message, error)
 }
 
 // MarkMessageRead 将邮件标记为已读
-func (s *Store) MarkMessageRead(mailboxID, messageID string) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+func (s *Store) MarkMessageRead(ctx context.Context, mailboxID, messageID string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
 		// 更新邮件状态
 		result := tx.Model(&domain.Message{}).
 			Where("id = ? AND mailbox_id = ? AND is_read = ?", messageID, mailboxID, false).
@@ -264,10 +677,34 @@ func (s *Store) MarkMessageRead(mailboxID, messageID string) error {
 	})
 }
 
+// MarkMessageUnread 将邮件标记为未读
+func (s *Store) MarkMessageUnread(ctx context.Context, mailboxID, messageID string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Message{}).
+			Where("id = ? AND mailbox_id = ? AND is_read = ?", messageID, mailboxID, true).
+			Update("is_read", false)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMessageNotFound
+		}
+
+		return tx.Model(&domain.Mailbox{}).
+			Where("id = ?", mailboxID).
+			UpdateColumn("unread", gorm.Expr("unread + 1")).
+			Error
+	})
+}
+
 // GetAttachment 获取邮件附件
-func (s *Store) GetAttachment(mailboxID, messageID, attachmentID string) (*domain.Attachment, error) {
+func (s *Store) GetAttachment(ctx context.Context, mailboxID, messageID, attachmentID string) (*domain.Attachment, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var attachment domain.Attachment
-	err := s.db.Where("id = ? AND message_id = ? AND mailbox_id = ?", attachmentID, messageID, mailboxID).First(&attachment).Error
+	err := db.Where("id = ? AND message_id = ? AND mailbox_id = ?", attachmentID, messageID, mailboxID).First(&attachment).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("attachment not found")
@@ -282,21 +719,27 @@ func (s *Store) GetAttachment(mailboxID, messageID, attachmentID string) (*domai
 // ========== API Key Repository ==========
 
 // SaveAPIKey 保存API Key
-func (s *Store) SaveAPIKey(apiKey *domain.APIKey) error {
+func (s *Store) SaveAPIKey(ctx context.Context, apiKey *domain.APIKey) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	if apiKey.ID == "" {
 		apiKey.ID = uuid.New().String()
 	}
 
-	now := time.Now().UTC()
-	apiKey.CreatedAt = now
+	// 保留已有的创建时间（数据迁移）
+	if apiKey.CreatedAt.IsZero() {
+		apiKey.CreatedAt = time.Now().UTC()
+	}
 
-	return s.db.Create(apiKey).Error
+	return db.Create(apiKey).Error
 }
 
 // GetAPIKey 根据ID获取API Key
-func (s *Store) GetAPIKey(id string) (*domain.APIKey, error) {
+func (s *Store) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var apiKey domain.APIKey
-	err := s.db.Where("id = ?", id).First(&apiKey).Error
+	err := db.Where("id = ?", id).First(&apiKey).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrUserNotFound
@@ -307,9 +750,11 @@ func (s *Store) GetAPIKey(id string) (*domain.APIKey, error) {
 }
 
 // GetAPIKeyByKey 根据Key字符串获取API Key
-func (s *Store) GetAPIKeyByKey(key string) (*domain.APIKey, error) {
+func (s *Store) GetAPIKeyByKey(ctx context.Context, key string) (*domain.APIKey, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var apiKey domain.APIKey
-	err := s.db.Where("key_hash = ?", key).First(&apiKey).Error
+	err := db.Where("key_hash = ?", key).First(&apiKey).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrUserNotFound
@@ -320,15 +765,19 @@ func (s *Store) GetAPIKeyByKey(key string) (*domain.APIKey, error) {
 }
 
 // ListAPIKeysByUserID 列出用户的所有API Key
-func (s *Store) ListAPIKeysByUserID(userID string) ([]*domain.APIKey, error) {
+func (s *Store) ListAPIKeysByUserID(ctx context.Context, userID string) ([]*domain.APIKey, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var apiKeys []*domain.APIKey
-	err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&apiKeys).Error
+	err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&apiKeys).Error
 	return apiKeys, err
 }
 
 // DeleteAPIKey 删除API Key
-func (s *Store) DeleteAPIKey(id string) error {
-	result := s.db.Where("id = ?", id).Delete(&domain.APIKey{})
+func (s *Store) DeleteAPIKey(ctx context.Context, id string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result := db.Where("id = ?", id).Delete(&domain.APIKey{})
 	if result.Error != nil {
 		return result.Error
 	}
@@ -339,9 +788,11 @@ func (s *Store) DeleteAPIKey(id string) error {
 }
 
 // UpdateAPIKeyLastUsed 更新API Key最后使用时间
-func (s *Store) UpdateAPIKeyLastUsed(id string) error {
+func (s *Store) UpdateAPIKeyLastUsed(ctx context.Context, id string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	now := time.Now().UTC()
-	result := s.db.Model(&domain.APIKey{}).
+	result := db.Model(&domain.APIKey{}).
 		Where("id = ?", id).
 		Update("last_used_at", now)
 
@@ -355,9 +806,11 @@ func (s *Store) UpdateAPIKeyLastUsed(id string) error {
 }
 
 // GetUserByAPIKey 根据API Key获取用户
-func (s *Store) GetUserByAPIKey(apiKey string) (*domain.User, error) {
+func (s *Store) GetUserByAPIKey(ctx context.Context, apiKey string) (*domain.User, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var user domain.User
-	err := s.db.Table("users").
+	err := db.Table("users").
 		Joins("JOIN api_keys ON users.id = api_keys.user_id").
 		Where("api_keys.key_hash = ? AND api_keys.is_active = ?", apiKey, true).
 		First(&user).Error
@@ -374,24 +827,30 @@ func (s *Store) GetUserByAPIKey(apiKey string) (*domain.User, error) {
 // ========== System Domain Repository ==========
 
 // SaveSystemDomain 保存系统域名
-func (s *Store) SaveSystemDomain(sysDomain *domain.SystemDomain) error {
+func (s *Store) SaveSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	if sysDomain.ID == "" {
 		sysDomain.ID = uuid.New().String()
 	}
 
-	now := time.Now().UTC()
-	sysDomain.CreatedAt = now
+	// 保留已有的创建时间（数据迁移）
+	if sysDomain.CreatedAt.IsZero() {
+		sysDomain.CreatedAt = time.Now().UTC()
+	}
 
-	return s.db.Create(sysDomain).Error
+	return db.Create(sysDomain).Error
 }
 
 // GetSystemDomain 根据ID获取系统域名
-func (s *Store) GetSystemDomain(domainID string) (*domain.SystemDomain, error) {
+func (s *Store) GetSystemDomain(ctx context.Context, domainID string) (*domain.SystemDomain, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var sysDomain domain.SystemDomain
-	err := s.db.Where("id = ?", domainID).First(&sysDomain).Error
+	err := db.Where("id = ?", domainID).First(&sysDomain).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("system domain not found")
+			return nil, ErrSystemDomainNotFound
 		}
 		return nil, err
 	}
@@ -399,12 +858,14 @@ func (s *Store) GetSystemDomain(domainID string) (*domain.SystemDomain, error) {
 }
 
 // GetSystemDomainByDomain 根据域名获取系统域名
-func (s *Store) GetSystemDomainByDomain(domainName string) (*domain.SystemDomain, error) {
+func (s *Store) GetSystemDomainByDomain(ctx context.Context, domainName string) (*domain.SystemDomain, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var sysDomain domain.SystemDomain
-	err := s.db.Where("domain = ?", domainName).First(&sysDomain).Error
+	err := db.Where("domain = ?", domainName).First(&sysDomain).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("system domain not found")
+			return nil, ErrSystemDomainNotFound
 		}
 		return nil, err
 	}
@@ -412,32 +873,40 @@ func (s *Store) GetSystemDomainByDomain(domainName string) (*domain.SystemDomain
 }
 
 // ListSystemDomains 获取所有系统域名
-func (s *Store) ListSystemDomains() ([]*domain.SystemDomain, error) {
+func (s *Store) ListSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var sysDomains []*domain.SystemDomain
-	err := s.db.Order("created_at DESC").Find(&sysDomains).Error
+	err := db.Order("created_at DESC").Find(&sysDomains).Error
 	return sysDomains, err
 }
 
 // UpdateSystemDomain 更新系统域名
-func (s *Store) UpdateSystemDomain(sysDomain *domain.SystemDomain) error {
-	return s.db.Save(sysDomain).Error
+func (s *Store) UpdateSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(sysDomain).Error
 }
 
 // DeleteSystemDomain 删除系统域名
-func (s *Store) DeleteSystemDomain(domainID string) error {
-	result := s.db.Where("id = ?", domainID).Delete(&domain.SystemDomain{})
+func (s *Store) DeleteSystemDomain(ctx context.Context, domainID string) error {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	result := db.Where("id = ?", domainID).Delete(&domain.SystemDomain{})
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("system domain not found")
+		return ErrSystemDomainNotFound
 	}
 	return nil
 }
 
 // SetDefaultSystemDomain 设置默认系统域名
-func (s *Store) SetDefaultSystemDomain(domainID string) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+func (s *Store) SetDefaultSystemDomain(ctx context.Context, domainID string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
 		// 先取消所有默认域名
 		if err := tx.Model(&domain.SystemDomain{}).
 			Where("is_default = ?", true).
@@ -454,7 +923,7 @@ func (s *Store) SetDefaultSystemDomain(domainID string) error {
 			return result.Error
 		}
 		if result.RowsAffected == 0 {
-			return fmt.Errorf("system domain not found")
+			return ErrSystemDomainNotFound
 		}
 
 		return nil
@@ -462,9 +931,11 @@ func (s *Store) SetDefaultSystemDomain(domainID string) error {
 }
 
 // GetDefaultSystemDomain 获取默认系统域名
-func (s *Store) GetDefaultSystemDomain() (*domain.SystemDomain, error) {
+func (s *Store) GetDefaultSystemDomain(ctx context.Context) (*domain.SystemDomain, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var sysDomain domain.SystemDomain
-	err := s.db.Where("is_default = ? AND is_active = ?", true, true).First(&sysDomain).Error
+	err := db.Where("is_default = ? AND is_active = ?", true, true).First(&sysDomain).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("no default system domain found")
@@ -475,31 +946,39 @@ func (s *Store) GetDefaultSystemDomain() (*domain.SystemDomain, error) {
 }
 
 // IncrementSystemDomainMailboxCount 增加系统域名邮箱计数
-func (s *Store) IncrementSystemDomainMailboxCount(domainName string) error {
-	return s.db.Model(&domain.SystemDomain{}).
+func (s *Store) IncrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Model(&domain.SystemDomain{}).
 		Where("domain = ?", domainName).
 		UpdateColumn("mailbox_count", gorm.Expr("mailbox_count + 1")).
 		Error
 }
 
 // DecrementSystemDomainMailboxCount 减少系统域名邮箱计数
-func (s *Store) DecrementSystemDomainMailboxCount(domainName string) error {
-	return s.db.Model(&domain.SystemDomain{}).
+func (s *Store) DecrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Model(&domain.SystemDomain{}).
 		Where("domain = ? AND mailbox_count > 0", domainName).
 		UpdateColumn("mailbox_count", gorm.Expr("mailbox_count - 1")).
 		Error
 }
 
 // ListActiveSystemDomains 获取所有已激活的系统域名
-func (s *Store) ListActiveSystemDomains() ([]*domain.SystemDomain, error) {
+func (s *Store) ListActiveSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var sysDomains []*domain.SystemDomain
-	err := s.db.Where("is_active = ?", true).Order("created_at DESC").Find(&sysDomains).Error
+	err := db.Where("is_active = ?", true).Order("created_at DESC").Find(&sysDomains).Error
 	return sysDomains, err
 }
 
 // DeleteUnverifiedSystemDomains 删除指定时间前创建且未验证的域名
-func (s *Store) DeleteUnverifiedSystemDomains(before time.Time) (int, error) {
-	result := s.db.Where("status != ? AND created_at < ?", domain.SystemDomainStatusVerified, before).Delete(&domain.SystemDomain{})
+func (s *Store) DeleteUnverifiedSystemDomains(ctx context.Context, before time.Time) (int, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	result := db.Where("status != ? AND created_at < ?", domain.SystemDomainStatusVerified, before).Delete(&domain.SystemDomain{})
 	if result.Error != nil {
 		return 0, result.Error
 	}
@@ -509,10 +988,12 @@ func (s *Store) DeleteUnverifiedSystemDomains(before time.Time) (int, error) {
 // ========== User Repository ==========
 
 // CreateUser 创建新用户
-func (s *Store) CreateUser(user *domain.User) error {
+func (s *Store) CreateUser(ctx context.Context, user *domain.User) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	// 检查邮箱是否已存在
 	var existingUser domain.User
-	err := s.db.Where("email = ?", user.Email).First(&existingUser).Error
+	err := db.Where("email = ?", user.Email).First(&existingUser).Error
 	if err == nil {
 		return ErrEmailExists
 	}
@@ -525,17 +1006,24 @@ func (s *Store) CreateUser(user *domain.User) error {
 		user.ID = uuid.New().String()
 	}
 
+	// 如果时间戳为零值，则设置为当前时间（数据迁移时保留原值）
 	now := time.Now().UTC()
-	user.CreatedAt = now
-	user.UpdatedAt = now
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
 
-	return s.db.Create(user).Error
+	return db.Create(user).Error
 }
 
 // GetUserByID 根据ID获取用户
-func (s *Store) GetUserByID(id string) (*domain.User, error) {
+func (s *Store) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var user domain.User
-	err := s.db.Where("id = ?", id).First(&user).Error
+	err := db.Where("id = ?", id).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrUserNotFound
@@ -546,9 +1034,11 @@ func (s *Store) GetUserByID(id string) (*domain.User, error) {
 }
 
 // GetUserByEmail 根据邮箱获取用户
-func (s *Store) GetUserByEmail(email string) (*domain.User, error) {
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var user domain.User
-	err := s.db.Where("email = ?", email).First(&user).Error
+	err := db.Where("email = ?", email).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrUserNotFound
@@ -559,9 +1049,11 @@ func (s *Store) GetUserByEmail(email string) (*domain.User, error) {
 }
 
 // GetUserByUsername 根据用户名获取用户
-func (s *Store) GetUserByUsername(username string) (*domain.User, error) {
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var user domain.User
-	err := s.db.Where("lower(username) = ?", strings.ToLower(username)).First(&user).Error
+	err := db.Where("lower(username) = ?", strings.ToLower(username)).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrUserNotFound
@@ -572,15 +1064,19 @@ func (s *Store) GetUserByUsername(username string) (*domain.User, error) {
 }
 
 // UpdateUser 更新用户信息
-func (s *Store) UpdateUser(user *domain.User) error {
+func (s *Store) UpdateUser(ctx context.Context, user *domain.User) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	user.UpdatedAt = time.Now().UTC()
-	return s.db.Save(user).Error
+	return db.Save(user).Error
 }
 
 // UpdateLastLogin 更新用户最后登录时间
-func (s *Store) UpdateLastLogin(userID string) error {
+func (s *Store) UpdateLastLogin(ctx context.Context, userID string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	now := time.Now().UTC()
-	return s.db.Model(&domain.User{}).
+	return db.Model(&domain.User{}).
 		Where("id = ?", userID).
 		Updates(map[string]interface{}{
 			"last_login_at": now,
@@ -591,8 +1087,10 @@ func (s *Store) UpdateLastLogin(userID string) error {
 // ========== Admin Repository ==========
 
 // ListUsers 列出用户（支持分页和过滤）
-func (s *Store) ListUsers(page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) {
-	query := s.db.Model(&domain.User{})
+func (s *Store) ListUsers(ctx context.Context, page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	query := db.Model(&domain.User{})
 
 	// 搜索过滤
 	if search != "" {
@@ -630,10 +1128,12 @@ func (s *Store) ListUsers(page, pageSize int, search string, role *domain.UserRo
 }
 
 // DeleteUser 删除用户
-func (s *Store) DeleteUser(userID string) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 删除用户的所有邮箱
-		if err := s.DeleteMailboxesByUserID(userID); err != nil {
+func (s *Store) DeleteUser(ctx context.Context, userID string) error {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 删除用户的所有邮箱（必须使用同一事务，单连接的 SQLite 上另开事务会死锁）
+		if err := deleteUserMailboxesTx(tx, userID); err != nil {
 			return err
 		}
 
@@ -643,34 +1143,32 @@ func (s *Store) DeleteUser(userID string) error {
 }
 
 // DeleteMailboxesByUserID 删除用户的所有邮箱
-func (s *Store) DeleteMailboxesByUserID(userID string) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 查找用户的所有邮箱
-		var mailboxes []domain.Mailbox
-		if err := tx.Where("user_id = ?", userID).Find(&mailboxes).Error; err != nil {
-			return err
-		}
-
-		// 删除每个邮箱的相关数据
-		for _, mb := range mailboxes {
-			// 删除邮件
-			if err := tx.Where("mailbox_id = ?", mb.ID).Delete(&domain.Message{}).Error; err != nil {
-				return err
-			}
+func (s *Store) DeleteMailboxesByUserID(ctx context.Context, userID string) error {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
+		return deleteUserMailboxesTx(tx, userID)
+	})
+}
 
-			// 删除别名
-			if err := tx.Where("mailbox_id = ?", mb.ID).Delete(&domain.MailboxAlias{}).Error; err != nil {
-				return err
-			}
-		}
+// deleteUserMailboxesTx 在事务中删除用户的所有邮箱
+func deleteUserMailboxesTx(tx *gorm.DB, userID string) error {
+	var mailboxes []domain.Mailbox
+	if err := tx.Where("user_id = ?", userID).Find(&mailboxes).Error; err != nil {
+		return err
+	}
 
-		// 删除邮箱
-		return tx.Where("user_id = ?", userID).Delete(&domain.Mailbox{}).Error
-	})
+	ids := make([]string, 0, len(mailboxes))
+	for _, mb := range mailboxes {
+		ids = append(ids, mb.ID)
+	}
+	return deleteMailboxesTx(tx, ids)
 }
 
 // GetSystemStatistics 获取系统统计信息
-func (s *Store) GetSystemStatistics() (*domain.SystemStatistics, error) {
+func (s *Store) GetSystemStatistics(ctx context.Context) (*domain.SystemStatistics, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	stats := &domain.SystemStatistics{
 		UsersByTier:       make(map[domain.UserTier]int),
 		UsersByRole:       make(map[domain.UserRole]int),
@@ -680,37 +1178,37 @@ func (s *Store) GetSystemStatistics() (*domain.SystemStatistics, error) {
 
 	// 用户统计
 	var totalUsers, activeUsers int64
-	if err := s.db.Model(&domain.User{}).Count(&totalUsers).Error; err != nil {
+	if err := db.Model(&domain.User{}).Count(&totalUsers).Error; err != nil {
 		return nil, err
 	}
 	stats.TotalUsers = int(totalUsers)
 
-	if err := s.db.Model(&domain.User{}).Where("is_active = ?", true).Count(&activeUsers).Error; err != nil {
+	if err := db.Model(&domain.User{}).Where("is_active = ?", true).Count(&activeUsers).Error; err != nil {
 		return nil, err
 	}
 	stats.ActiveUsers = int(activeUsers)
 
 	// 邮箱统计
 	var totalMailboxes, activeMailboxes int64
-	if err := s.db.Model(&domain.Mailbox{}).Count(&totalMailboxes).Error; err != nil {
+	if err := db.Model(&domain.Mailbox{}).Count(&totalMailboxes).Error; err != nil {
 		return nil, err
 	}
 	stats.TotalMailboxes = int(totalMailboxes)
 
-	if err := s.db.Model(&domain.Mailbox{}).Where("expires_at IS NULL OR expires_at > ?", time.Now()).Count(&activeMailboxes).Error; err != nil {
+	if err := db.Model(&domain.Mailbox{}).Where("expires_at IS NULL OR expires_at > ?", time.Now()).Count(&activeMailboxes).Error; err != nil {
 		return nil, err
 	}
 	stats.ActiveMailboxes = int(activeMailboxes)
 
 	// 邮件统计
 	var totalMessages, messagesToday int64
-	if err := s.db.Model(&domain.Message{}).Count(&totalMessages).Error; err != nil {
+	if err := db.Model(&domain.Message{}).Count(&totalMessages).Error; err != nil {
 		return nil, err
 	}
 	stats.TotalMessages = int(totalMessages)
 
 	today := time.Now().Truncate(24 * time.Hour)
-	if err := s.db.Model(&domain.Message{}).Where("created_at >= ?", today).Count(&messagesToday).Error; err != nil {
+	if err := db.Model(&domain.Message{}).Where("created_at >= ?", today).Count(&messagesToday).Error; err != nil {
 		return nil, err
 	}
 	stats.MessagesToday = int(messagesToday)
@@ -720,7 +1218,7 @@ func (s *Store) GetSystemStatistics() (*domain.SystemStatistics, error) {
 		Tier  domain.UserTier `json:"tier"`
 		Count int             `json:"count"`
 	}
-	if err := s.db.Model(&domain.User{}).Select("tier, COUNT(*) as count").Group("tier").Scan(&tierStats).Error; err != nil {
+	if err := db.Model(&domain.User{}).Select("tier, COUNT(*) as count").Group("tier").Scan(&tierStats).Error; err != nil {
 		return nil, err
 	}
 	for _, stat := range tierStats {
@@ -732,7 +1230,7 @@ func (s *Store) GetSystemStatistics() (*domain.SystemStatistics, error) {
 		Role  domain.UserRole `json:"role"`
 		Count int             `json:"count"`
 	}
-	if err := s.db.Model(&domain.User{}).Select("role, COUNT(*) as count").Group("role").Scan(&roleStats).Error; err != nil {
+	if err := db.Model(&domain.User{}).Select("role, COUNT(*) as count").Group("role").Scan(&roleStats).Error; err != nil {
 		return nil, err
 	}
 	for _, stat := range roleStats {
@@ -744,7 +1242,7 @@ func (s *Store) GetSystemStatistics() (*domain.SystemStatistics, error) {
 		Domain string `json:"domain"`
 		Count  int    `json:"count"`
 	}
-	if err := s.db.Model(&domain.Mailbox{}).Select("domain, COUNT(*) as count").Group("domain").Scan(&domainStats).Error; err != nil {
+	if err := db.Model(&domain.Mailbox{}).Select("domain, COUNT(*) as count").Group("domain").Scan(&domainStats).Error; err != nil {
 		return nil, err
 	}
 	for _, stat := range domainStats {
@@ -755,17 +1253,19 @@ func (s *Store) GetSystemStatistics() (*domain.SystemStatistics, error) {
 }
 
 // GetDomainStatistics 获取域名统计信息
-func (s *Store) GetDomainStatistics(domainName string) (mailboxCount, messageCount int, err error) {
+func (s *Store) GetDomainStatistics(ctx context.Context, domainName string) (mailboxCount, messageCount int, err error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	// 统计邮箱数量
 	var mailboxCountInt64 int64
-	if err := s.db.Model(&domain.Mailbox{}).Where("domain = ?", domainName).Count(&mailboxCountInt64).Error; err != nil {
+	if err := db.Model(&domain.Mailbox{}).Where("domain = ?", domainName).Count(&mailboxCountInt64).Error; err != nil {
 		return 0, 0, err
 	}
 	mailboxCount = int(mailboxCountInt64)
 
 	// 统计邮件数量
 	var messageCountInt64 int64
-	if err := s.db.Model(&domain.Message{}).
+	if err := db.Model(&domain.Message{}).
 		Joins("JOIN mailboxes ON messages.mailbox_id = mailboxes.id").
 		Where("mailboxes.domain = ?", domainName).
 		Count(&messageCountInt64).Error; err != nil {
@@ -779,8 +1279,10 @@ func (s *Store) GetDomainStatistics(domainName string) (mailboxCount, messageCou
 // ========== Alias Repository ==========
 
 // SaveAlias 保存邮箱别名
-func (s *Store) SaveAlias(alias *domain.MailboxAlias) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+func (s *Store) SaveAlias(ctx context.Context, alias *domain.MailboxAlias) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	err := db.Transaction(func(tx *gorm.DB) error {
 		// 检查邮箱是否存在
 		var mailbox domain.Mailbox
 		if err := tx.Where("id = ?", alias.MailboxID).First(&mailbox).Error; err != nil {
@@ -793,7 +1295,7 @@ func (s *Store) SaveAlias(alias *domain.MailboxAlias) error {
 		// 检查别名地址是否已被使用
 		var existingMailbox domain.Mailbox
 		if err := tx.Where("address = ?", alias.Address).First(&existingMailbox).Error; err == nil {
-			return fmt.Errorf("address already exists as mailbox")
+			return storage.ErrAddressTaken
 		}
 
 		// 检查地址是否被其他别名使用
@@ -808,12 +1310,30 @@ func (s *Store) SaveAlias(alias *domain.MailboxAlias) error {
 
 		return tx.Save(alias).Error
 	})
+	if err != nil {
+		return err
+	}
+
+	// 提交后再确认没有同时创建的同地址邮箱（见 CreateMailbox）
+	var mailboxes int64
+	if err := db.Model(&domain.Mailbox{}).Where("address = ?", alias.Address).Count(&mailboxes).Error; err != nil {
+		return err
+	}
+	if mailboxes > 0 {
+		if err := db.Where("id = ?", alias.ID).Delete(&domain.MailboxAlias{}).Error; err != nil {
+			return err
+		}
+		return storage.ErrAddressTaken
+	}
+	return nil
 }
 
 // GetAlias 根据ID获取别名
-func (s *Store) GetAlias(aliasID string) (*domain.MailboxAlias, error) {
+func (s *Store) GetAlias(ctx context.Context, aliasID string) (*domain.MailboxAlias, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var alias domain.MailboxAlias
-	err := s.db.Where("id = ?", aliasID).First(&alias).Error
+	err := db.Where("id = ?", aliasID).First(&alias).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrAliasNotFound
@@ -824,9 +1344,11 @@ func (s *Store) GetAlias(aliasID string) (*domain.MailboxAlias, error) {
 }
 
 // GetAliasByAddress 根据地址获取别名
-func (s *Store) GetAliasByAddress(address string) (*domain.MailboxAlias, error) {
+func (s *Store) GetAliasByAddress(ctx context.Context, address string) (*domain.MailboxAlias, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var alias domain.MailboxAlias
-	err := s.db.Where("address = ?", address).First(&alias).Error
+	err := db.Where("address = ?", address).First(&alias).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrAliasNotFound
@@ -837,28 +1359,32 @@ func (s *Store) GetAliasByAddress(address string) (*domain.MailboxAlias, error)
 }
 
 // ListAliasesByMailboxID 列出指定邮箱的所有别名
-func (s *Store) ListAliasesByMailboxID(mailboxID string) ([]*domain.MailboxAlias, error) {
+func (s *Store) ListAliasesByMailboxID(ctx context.Context, mailboxID string) ([]*domain.MailboxAlias, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var aliases []*domain.MailboxAlias
-	err := s.db.Where("mailbox_id = ?", mailboxID).Find(&aliases).Error
+	err := db.Where("mailbox_id = ?", mailboxID).Find(&aliases).Error
 	return aliases, err
 }
 
 // DeleteAlias 删除别名
-func (s *Store) DeleteAlias(aliasID string) error {
-	return s.db.Where("id = ?", aliasID).Delete(&domain.MailboxAlias{}).Error
+func (s *Store) DeleteAlias(ctx context.Context, aliasID string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Where("id = ?", aliasID).Delete(&domain.MailboxAlias{}).Error
 }
 
 // ========== System Config Repository ==========
 
 // GetSystemConfig 获取系统配置
-func (s *Store) GetSystemConfig() (*domain.SystemConfig, error) {
+func (s *Store) GetSystemConfig(ctx context.Context) (*domain.SystemConfig, error) {
 	// TODO: 实现数据库存储
 	// 目前返回默认配置
 	return domain.DefaultSystemConfig(), nil
 }
 
 // SaveSystemConfig 保存系统配置
-func (s *Store) SaveSystemConfig(config *domain.SystemConfig) error {
+func (s *Store) SaveSystemConfig(ctx context.Context, config *domain.SystemConfig) error {
 	// TODO: 实现数据库存储
 	// 目前只返回成功
 	return nil
@@ -867,14 +1393,18 @@ func (s *Store) SaveSystemConfig(config *domain.SystemConfig) error {
 // ========== User Domain Repository ==========
 
 // SaveUserDomain 保存用户域名
-func (s *Store) SaveUserDomain(userDomain *domain.UserDomain) error {
-	return s.db.Save(userDomain).Error
+func (s *Store) SaveUserDomain(ctx context.Context, userDomain *domain.UserDomain) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(userDomain).Error
 }
 
 // GetUserDomain 根据ID获取用户域名
-func (s *Store) GetUserDomain(domainID string) (*domain.UserDomain, error) {
+func (s *Store) GetUserDomain(ctx context.Context, domainID string) (*domain.UserDomain, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var userDomain domain.UserDomain
-	err := s.db.Where("id = ?", domainID).First(&userDomain).Error
+	err := db.Where("id = ?", domainID).First(&userDomain).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("user domain not found")
@@ -885,9 +1415,11 @@ func (s *Store) GetUserDomain(domainID string) (*domain.UserDomain, error) {
 }
 
 // GetUserDomainByDomain 根据域名获取用户域名
-func (s *Store) GetUserDomainByDomain(domainName string) (*domain.UserDomain, error) {
+func (s *Store) GetUserDomainByDomain(ctx context.Context, domainName string) (*domain.UserDomain, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var userDomain domain.UserDomain
-	err := s.db.Where("domain = ?", domainName).First(&userDomain).Error
+	err := db.Where("domain = ?", domainName).First(&userDomain).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("user domain not found")
@@ -898,20 +1430,35 @@ func (s *Store) GetUserDomainByDomain(domainName string) (*domain.UserDomain, er
 }
 
 // ListUserDomainsByUserID 列出用户的所有域名
-func (s *Store) ListUserDomainsByUserID(userID string) ([]*domain.UserDomain, error) {
+func (s *Store) ListUserDomainsByUserID(ctx context.Context, userID string) ([]*domain.UserDomain, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var userDomains []*domain.UserDomain
-	err := s.db.Where("user_id = ?", userID).Find(&userDomains).Error
+	err := db.Where("user_id = ?", userID).Find(&userDomains).Error
+	return userDomains, err
+}
+
+// ListUserDomainsByOrgID 获取组织的所有域名
+func (s *Store) ListUserDomainsByOrgID(ctx context.Context, orgID string) ([]*domain.UserDomain, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var userDomains []*domain.UserDomain
+	err := db.Where("org_id = ?", orgID).Find(&userDomains).Error
 	return userDomains, err
 }
 
 // UpdateUserDomain 更新用户域名
-func (s *Store) UpdateUserDomain(userDomain *domain.UserDomain) error {
-	return s.db.Save(userDomain).Error
+func (s *Store) UpdateUserDomain(ctx context.Context, userDomain *domain.UserDomain) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Save(userDomain).Error
 }
 
 // DeleteUserDomain 删除用户域名
-func (s *Store) DeleteUserDomain(domainID string) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+func (s *Store) DeleteUserDomain(ctx context.Context, domainID string) error {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
 		// 检查是否有活跃邮箱
 		var count int64
 		if err := tx.Model(&domain.Mailbox{}).Where("domain IN (SELECT domain FROM user_domains WHERE id = ?)", domainID).Count(&count).Error; err != nil {
@@ -920,25 +1467,48 @@ func (s *Store) DeleteUserDomain(domainID string) error {
 		if count > 0 {
 			return fmt.Errorf("cannot delete domain with active mailboxes")
 		}
+		if err := tx.Where("domain_id = ?", domainID).Delete(&domain.DomainWhitelistEntry{}).Error; err != nil {
+			return err
+		}
 
 		return tx.Where("id = ?", domainID).Delete(&domain.UserDomain{}).Error
 	})
 }
 
 // IncrementMailboxCount 增加域名邮箱计数
-func (s *Store) IncrementMailboxCount(domainName string) error {
-	return s.db.Model(&domain.UserDomain{}).
+func (s *Store) IncrementMailboxCount(ctx context.Context, domainName string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Model(&domain.UserDomain{}).
 		Where("domain = ?", domainName).
 		UpdateColumn("mailbox_count", gorm.Expr("mailbox_count + 1")).Error
 }
 
 // DecrementMailboxCount 减少域名邮箱计数
-func (s *Store) DecrementMailboxCount(domainName string) error {
-	return s.db.Model(&domain.UserDomain{}).
+func (s *Store) DecrementMailboxCount(ctx context.Context, domainName string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Model(&domain.UserDomain{}).
 		Where("domain = ? AND mailbox_count > 0", domainName).
 		UpdateColumn("mailbox_count", gorm.Expr("mailbox_count - 1")).Error
 }
 
+// WithTransaction 在同一个数据库事务中执行 fn，fn 返回错误时整体回滚
+func (s *Store) WithTransaction(fn func(tx *Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Store{db: tx})
+	})
+}
+
+// Ping 检查数据库连接是否可用
+func (s *Store) Ping(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
 // Close 关闭数据库连接
 func (s *Store) Close() error {
 	sqlDB, err := s.db.DB()
@@ -949,9 +1519,11 @@ func (s *Store) Close() error {
 }
 
 // ListAllUserDomains 列出所有用户域名
-func (s *Store) ListAllUserDomains() ([]*domain.UserDomain, error) {
+func (s *Store) ListAllUserDomains(ctx context.Context) ([]*domain.UserDomain, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var userDomains []*domain.UserDomain
-	err := s.db.Find(&userDomains).Error
+	err := db.Find(&userDomains).Error
 	if err != nil {
 		return nil, err
 	}
@@ -965,22 +1537,31 @@ func (s *Store) ListAllUserDomains() ([]*domain.UserDomain, error) {
 // ========== Tag Repository ==========
 
 // CreateTag 创建标签
-func (s *Store) CreateTag(tag *domain.Tag) error {
+func (s *Store) CreateTag(ctx context.Context, tag *domain.Tag) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	if tag.ID == "" {
 		tag.ID = uuid.New().String()
 	}
 
+	// 如果时间戳为零值，则设置为当前时间（数据迁移时保留原值）
 	now := time.Now().UTC()
-	tag.CreatedAt = now
-	tag.UpdatedAt = now
+	if tag.CreatedAt.IsZero() {
+		tag.CreatedAt = now
+	}
+	if tag.UpdatedAt.IsZero() {
+		tag.UpdatedAt = now
+	}
 
-	return s.db.Create(tag).Error
+	return db.Create(tag).Error
 }
 
 // GetTag 根据ID获取标签
-func (s *Store) GetTag(id string) (*domain.Tag, error) {
+func (s *Store) GetTag(ctx context.Context, id string) (*domain.Tag, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var tag domain.Tag
-	err := s.db.Where("id = ?", id).First(&tag).Error
+	err := db.Where("id = ?", id).First(&tag).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("tag not found")
@@ -991,9 +1572,11 @@ func (s *Store) GetTag(id string) (*domain.Tag, error) {
 }
 
 // GetTagByName 根据用户ID和标签名获取标签
-func (s *Store) GetTagByName(userID, name string) (*domain.Tag, error) {
+func (s *Store) GetTagByName(ctx context.Context, userID, name string) (*domain.Tag, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var tag domain.Tag
-	err := s.db.Where("user_id = ? AND name = ?", userID, name).First(&tag).Error
+	err := db.Where("user_id = ? AND name = ?", userID, name).First(&tag).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("tag not found")
@@ -1004,10 +1587,12 @@ func (s *Store) GetTagByName(userID, name string) (*domain.Tag, error) {
 }
 
 // ListTags 列出用户的所有标签（带使用计数）
-func (s *Store) ListTags(userID string) ([]domain.TagWithCount, error) {
+func (s *Store) ListTags(ctx context.Context, userID string) ([]domain.TagWithCount, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var results []domain.TagWithCount
 
-	err := s.db.Table("tags").
+	err := db.Table("tags").
 		Select("tags.*, COUNT(message_tags.tag_id) as message_count").
 		Joins("LEFT JOIN message_tags ON tags.id = message_tags.tag_id").
 		Where("tags.user_id = ?", userID).
@@ -1018,15 +1603,36 @@ func (s *Store) ListTags(userID string) ([]domain.TagWithCount, error) {
 	return results, err
 }
 
+// ListTagsByOrgID 列出组织的所有标签（带邮件计数）
+func (s *Store) ListTagsByOrgID(ctx context.Context, orgID string) ([]domain.TagWithCount, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var results []domain.TagWithCount
+
+	err := db.Table("tags").
+		Select("tags.*, COUNT(message_tags.tag_id) as message_count").
+		Joins("LEFT JOIN message_tags ON tags.id = message_tags.tag_id").
+		Where("tags.org_id = ?", orgID).
+		Group("tags.id").
+		Order("tags.created_at DESC").
+		Scan(&results).Error
+
+	return results, err
+}
+
 // UpdateTag 更新标签
-func (s *Store) UpdateTag(tag *domain.Tag) error {
+func (s *Store) UpdateTag(ctx context.Context, tag *domain.Tag) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	tag.UpdatedAt = time.Now().UTC()
-	return s.db.Save(tag).Error
+	return db.Save(tag).Error
 }
 
 // DeleteTag 删除标签
-func (s *Store) DeleteTag(id string) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+func (s *Store) DeleteTag(ctx context.Context, id string) error {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
 		// 先删除标签与邮件的关联
 		if err := tx.Where("tag_id = ?", id).Delete(&domain.MessageTag{}).Error; err != nil {
 			return err
@@ -1046,7 +1652,9 @@ func (s *Store) DeleteTag(id string) error {
 }
 
 // AddMessageTag 为邮件添加标签
-func (s *Store) AddMessageTag(messageID, tagID string) error {
+func (s *Store) AddMessageTag(ctx context.Context, messageID, tagID string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	messageTag := &domain.MessageTag{
 		MessageID: messageID,
 		TagID:     tagID,
@@ -1054,12 +1662,14 @@ func (s *Store) AddMessageTag(messageID, tagID string) error {
 	}
 
 	// 使用 ON CONFLICT DO NOTHING 避免重复添加
-	return s.db.Create(messageTag).Error
+	return db.Create(messageTag).Error
 }
 
 // RemoveMessageTag 移除邮件标签
-func (s *Store) RemoveMessageTag(messageID, tagID string) error {
-	result := s.db.Where("message_id = ? AND tag_id = ?", messageID, tagID).Delete(&domain.MessageTag{})
+func (s *Store) RemoveMessageTag(ctx context.Context, messageID, tagID string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result := db.Where("message_id = ? AND tag_id = ?", messageID, tagID).Delete(&domain.MessageTag{})
 	if result.Error != nil {
 		return result.Error
 	}
@@ -1070,10 +1680,12 @@ func (s *Store) RemoveMessageTag(messageID, tagID string) error {
 }
 
 // GetMessageTags 获取邮件的所有标签
-func (s *Store) GetMessageTags(messageID string) ([]domain.Tag, error) {
+func (s *Store) GetMessageTags(ctx context.Context, messageID string) ([]domain.Tag, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
 	var tags []domain.Tag
 
-	err := s.db.Table("tags").
+	err := db.Table("tags").
 		Joins("JOIN message_tags ON tags.id = message_tags.tag_id").
 		Where("message_tags.message_id = ?", messageID).
 		Order("tags.name").
@@ -1083,40 +1695,158 @@ func (s *Store) GetMessageTags(messageID string) ([]domain.Tag, error) {
 }
 
 // ListMessagesByTag 列出标签下的所有邮件
-func (s *Store) ListMessagesByTag(tagID string) ([]domain.Message, error) {
+func (s *Store) ListMessagesByTag(ctx context.Context, tagID string) ([]domain.Message, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
 	var messages []domain.Message
 
-	err := s.db.Table("messages").
+	err := db.Table("messages").
 		Joins("JOIN message_tags ON messages.id = message_tags.message_id").
 		Where("message_tags.tag_id = ?", tagID).
-		Order("messages.created_at DESC").
+		Order("messages.created_at DESC, messages.seq DESC").
 		Find(&messages).Error
 
 	return messages, err
 }
 
 // DeleteMessageTags 删除邮件的所有标签
-func (s *Store) DeleteMessageTags(messageID string) error {
-	return s.db.Where("message_id = ?", messageID).Delete(&domain.MessageTag{}).Error
+func (s *Store) DeleteMessageTags(ctx context.Context, messageID string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Where("message_id = ?", messageID).Delete(&domain.MessageTag{}).Error
 }
 
 // DeleteMessage 删除单封邮件
-func (s *Store) DeleteMessage(mailboxID, messageID string) error {
-	result := s.db.Where("id = ? AND mailbox_id = ?", messageID, mailboxID).Delete(&domain.Message{})
+func (s *Store) DeleteMessage(ctx context.Context, mailboxID, messageID string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
+		var message domain.Message
+		if err := tx.Select("id", "is_read").Where("id = ? AND mailbox_id = ?", messageID, mailboxID).Take(&message).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrMessageNotFound
+			}
+			return err
+		}
+		result := tx.Where("id = ? AND mailbox_id = ?", messageID, mailboxID).Delete(&domain.Message{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMessageNotFound
+		}
+		if err := tx.Where("message_id = ?", messageID).Delete(&domain.SearchDocument{}).Error; err != nil {
+			return err
+		}
+		// 更新邮箱统计
+		updates := map[string]interface{}{"total_count": gorm.Expr("total_count - 1")}
+		if !message.IsRead {
+			updates["unread"] = gorm.Expr("unread - 1")
+		}
+		return tx.Model(&domain.Mailbox{}).Where("id = ?", mailboxID).Updates(updates).Error
+	})
+}
+
+// DeleteAllMessages 删除邮箱所有消息，返回删除数量
+func (s *Store) DeleteAllMessages(ctx context.Context, mailboxID string) (int, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+	var count int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("mailbox_id = ?", mailboxID).Delete(&domain.Message{})
+		if result.Error != nil {
+			return result.Error
+		}
+		count = result.RowsAffected
+		if err := tx.Where("mailbox_id = ?", mailboxID).Delete(&domain.SearchDocument{}).Error; err != nil {
+			return err
+		}
+		// 重置邮箱统计
+		return tx.Model(&domain.Mailbox{}).Where("id = ?", mailboxID).Updates(map[string]interface{}{
+			"total_count": 0,
+			"unread":      0,
+		}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// SetMessageExpiry 设置单封邮件的删除时间
+func (s *Store) SetMessageExpiry(ctx context.Context, mailboxID, messageID string, expiresAt *time.Time) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	return db.Model(&domain.Message{}).
+		Where("id = ? AND mailbox_id = ?", messageID, mailboxID).
+		Update("expires_at", expiresAt).Error
+}
+
+// SetMessageQuarantined 将邮件移入或移出隔离区
+func (s *Store) SetMessageQuarantined(ctx context.Context, mailboxID, messageID string, quarantined bool) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+	result := db.Model(&domain.Message{}).
+		Where("id = ? AND mailbox_id = ?", messageID, mailboxID).
+		Update("quarantined", quarantined)
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("message not found")
+		return ErrMessageNotFound
 	}
 	return nil
 }
 
-// DeleteAllMessages 删除邮箱所有消息，返回删除数量
-func (s *Store) DeleteAllMessages(mailboxID string) (int, error) {
-	result := s.db.Where("mailbox_id = ?", mailboxID).Delete(&domain.Message{})
-	if result.Error != nil {
-		return 0, result.Error
+// DeleteExpiredMessages 删除按规则到期的邮件，每个邮箱在一个事务内删除并扣减统计
+func (s *Store) DeleteExpiredMessages(ctx context.Context, now time.Time) ([]domain.ExpiredMessages, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var rows []struct {
+		ID        string
+		MailboxID string
 	}
-	return int(result.RowsAffected), nil
+	if err := db.Model(&domain.Message{}).Select("id, mailbox_id").
+		Where("expires_at IS NOT NULL AND expires_at <= ?", now).
+		Order("mailbox_id, id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var expired []domain.ExpiredMessages
+	for _, row := range rows {
+		if n := len(expired); n == 0 || expired[n-1].MailboxID != row.MailboxID {
+			expired = append(expired, domain.ExpiredMessages{MailboxID: row.MailboxID})
+		}
+		batch := &expired[len(expired)-1]
+		batch.MessageIDs = append(batch.MessageIDs, row.ID)
+	}
+
+	for i := range expired {
+		batch := &expired[i]
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// 未读邮件单独删除，并发标记已读时不会重复扣减未读数
+			unread := tx.Where("id IN ? AND is_read = ?", batch.MessageIDs, false).Delete(&domain.Message{})
+			if unread.Error != nil {
+				return unread.Error
+			}
+			read := tx.Where("id IN ?", batch.MessageIDs).Delete(&domain.Message{})
+			if read.Error != nil {
+				return read.Error
+			}
+			batch.Unread = int(unread.RowsAffected)
+			if err := tx.Where("message_id IN ?", batch.MessageIDs).Delete(&domain.SearchDocument{}).Error; err != nil {
+				return err
+			}
+			return tx.Model(&domain.Mailbox{}).Where("id = ?", batch.MailboxID).Updates(map[string]interface{}{
+				"total_count": gorm.Expr("total_count - ?", unread.RowsAffected+read.RowsAffected),
+				"unread":      gorm.Expr("unread - ?", unread.RowsAffected),
+			}).Error
+		})
+		if err != nil {
+			return expired[:i], err
+		}
+	}
+	return expired, nil
 }
@@ -0,0 +1,23 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 存储边界的默认超时：数据库挂起时调用方得到明确的错误，而不是一直阻塞。
+// ctx 已有更早的截止时间或被取消时以 ctx 为准。
+const (
+	// pointTimeout 单条记录的读写
+	pointTimeout = 5 * time.Second
+	// bulkTimeout 列表、搜索、统计、批量写入和级联删除（个人数据导出也走这些查询）
+	bulkTimeout = 30 * time.Second
+)
+
+// withTimeout 返回绑定 ctx 并加上超时的会话，调用方必须调用返回的 cancel
+func (s *Store) withTimeout(ctx context.Context, timeout time.Duration) (*gorm.DB, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return s.db.WithContext(ctx), cancel
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// ========== User Session Repository ==========
+
+// CreateUserSession 创建登录会话
+func (s *Store) CreateUserSession(ctx context.Context, session *domain.UserSession) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	return db.Create(session).Error
+}
+
+// GetUserSession 按ID获取登录会话
+func (s *Store) GetUserSession(ctx context.Context, id string) (*domain.UserSession, error) {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	var session domain.UserSession
+	if err := db.Where("id = ?", id).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrUserSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RotateUserSession 以刷新令牌 jti 做条件更新，并发刷新时只有一个成功
+func (s *Store) RotateUserSession(ctx context.Context, session *domain.UserSession, previousRefreshID string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result := db.Model(&domain.UserSession{}).
+		Where("id = ? AND refresh_token_id = ?", session.ID, previousRefreshID).
+		Updates(map[string]interface{}{
+			"refresh_token_id":  session.RefreshTokenID,
+			"access_token_id":   session.AccessTokenID,
+			"access_expires_at": session.AccessExpiresAt,
+			"ip":                session.IP,
+			"user_agent":        session.UserAgent,
+			"last_used_at":      session.LastUsedAt,
+			"expires_at":        session.ExpiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	var count int64
+	if err := db.Model(&domain.UserSession{}).Where("id = ?", session.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrUserSessionNotFound
+	}
+	return storage.ErrUserSessionRotated
+}
+
+// ListUserSessions 列出用户的会话（按最近使用时间倒序）
+func (s *Store) ListUserSessions(ctx context.Context, userID string) ([]*domain.UserSession, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	var sessions []*domain.UserSession
+	if err := db.Where("user_id = ?", userID).Order("last_used_at DESC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DeleteUserSession 删除登录会话
+func (s *Store) DeleteUserSession(ctx context.Context, id string) error {
+	db, cancel := s.withTimeout(ctx, pointTimeout)
+	defer cancel()
+
+	result := db.Where("id = ?", id).Delete(&domain.UserSession{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return storage.ErrUserSessionNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredUserSessions 删除已过期的登录会话
+func (s *Store) DeleteExpiredUserSessions(ctx context.Context, now time.Time) (int64, error) {
+	db, cancel := s.withTimeout(ctx, bulkTimeout)
+	defer cancel()
+
+	result := db.Where("expires_at <= ?", now).Delete(&domain.UserSession{})
+	return result.RowsAffected, result.Error
+}
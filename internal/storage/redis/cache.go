@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage/cachekey"
 )
 
 // Cache Redis 缓存实现
@@ -42,7 +44,7 @@ func NewCache(addr, password string, db int) (*Cache, error) {
 
 // CacheMailbox 缓存邮箱信息
 func (c *Cache) CacheMailbox(mailbox *domain.Mailbox, ttl time.Duration) error {
-	key := fmt.Sprintf("mailbox:%s", mailbox.ID)
+	key := cachekey.Mailbox(mailbox.ID).String()
 	data, err := json.Marshal(mailbox)
 	if err != nil {
 		return err
@@ -52,7 +54,7 @@ func (c *Cache) CacheMailbox(mailbox *domain.Mailbox, ttl time.Duration) error {
 
 // GetCachedMailbox 获取缓存的邮箱信息
 func (c *Cache) GetCachedMailbox(mailboxID string) (*domain.Mailbox, error) {
-	key := fmt.Sprintf("mailbox:%s", mailboxID)
+	key := cachekey.Mailbox(mailboxID).String()
 	data, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -69,17 +71,40 @@ func (c *Cache) GetCachedMailbox(mailboxID string) (*domain.Mailbox, error) {
 	return &mailbox, nil
 }
 
-// DeleteCachedMailbox 删除缓存的邮箱信息
-func (c *Cache) DeleteCachedMailbox(mailboxID string) error {
-	key := fmt.Sprintf("mailbox:%s", mailboxID)
-	return c.client.Del(c.ctx, key).Err()
+// CacheMailboxSummaries 缓存用户的邮箱摘要列表
+func (c *Cache) CacheMailboxSummaries(userID string, summaries []domain.MailboxSummary, ttl time.Duration) error {
+	key := cachekey.MailboxSummaries(userID).String()
+	data, err := json.Marshal(summaries)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(c.ctx, key, data, ttl).Err()
+}
+
+// GetCachedMailboxSummaries 获取缓存的用户邮箱摘要列表
+func (c *Cache) GetCachedMailboxSummaries(userID string) ([]domain.MailboxSummary, error) {
+	key := cachekey.MailboxSummaries(userID).String()
+	data, err := c.client.Get(c.ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("mailbox summaries not found in cache")
+		}
+		return nil, err
+	}
+
+	var summaries []domain.MailboxSummary
+	if err := json.Unmarshal([]byte(data), &summaries); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
 }
 
 // ========== 邮件缓存 ==========
 
 // CacheMessage 缓存邮件信息
 func (c *Cache) CacheMessage(message *domain.Message, ttl time.Duration) error {
-	key := fmt.Sprintf("message:%s:%s", message.MailboxID, message.ID)
+	key := cachekey.Message(message.MailboxID, message.ID).String()
 	data, err := json.Marshal(message)
 	if err != nil {
 		return err
@@ -89,7 +114,7 @@ func (c *Cache) CacheMessage(message *domain.Message, ttl time.Duration) error {
 
 // GetCachedMessage 获取缓存的邮件信息
 func (c *Cache) GetCachedMessage(mailboxID, messageID string) (*domain.Message, error) {
-	key := fmt.Sprintf("message:%s:%s", mailboxID, messageID)
+	key := cachekey.Message(mailboxID, messageID).String()
 	data, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -108,7 +133,7 @@ func (c *Cache) GetCachedMessage(mailboxID, messageID string) (*domain.Message,
 
 // CacheMessageList 缓存邮件列表
 func (c *Cache) CacheMessageList(mailboxID string, messages []domain.Message, ttl time.Duration) error {
-	key := fmt.Sprintf("messages:%s", mailboxID)
+	key := cachekey.MessageList(mailboxID).String()
 	data, err := json.Marshal(messages)
 	if err != nil {
 		return err
@@ -118,7 +143,7 @@ func (c *Cache) CacheMessageList(mailboxID string, messages []domain.Message, tt
 
 // GetCachedMessageList 获取缓存的邮件列表
 func (c *Cache) GetCachedMessageList(mailboxID string) ([]domain.Message, error) {
-	key := fmt.Sprintf("messages:%s", mailboxID)
+	key := cachekey.MessageList(mailboxID).String()
 	data, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -135,17 +160,27 @@ func (c *Cache) GetCachedMessageList(mailboxID string) ([]domain.Message, error)
 	return messages, nil
 }
 
-// DeleteCachedMessageList 删除缓存的邮件列表
-func (c *Cache) DeleteCachedMessageList(mailboxID string) error {
-	key := fmt.Sprintf("messages:%s", mailboxID)
-	return c.client.Del(c.ctx, key).Err()
+// DeleteCachedMessages 删除邮箱下所有单封邮件缓存
+func (c *Cache) DeleteCachedMessages(mailboxID string) error {
+	iter := c.client.Scan(c.ctx, 0, cachekey.MessagePattern(mailboxID).String(), 100).Iterator()
+	keys := make([]string, 0)
+	for iter.Next(c.ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.client.Del(c.ctx, keys...).Err()
 }
 
 // ========== 用户缓存 ==========
 
 // CacheUser 缓存用户信息
 func (c *Cache) CacheUser(user *domain.User, ttl time.Duration) error {
-	key := fmt.Sprintf("user:%s", user.ID)
+	key := cachekey.User(user.ID).String()
 	data, err := json.Marshal(user)
 	if err != nil {
 		return err
@@ -155,7 +190,7 @@ func (c *Cache) CacheUser(user *domain.User, ttl time.Duration) error {
 
 // GetCachedUser 获取缓存的用户信息
 func (c *Cache) GetCachedUser(userID string) (*domain.User, error) {
-	key := fmt.Sprintf("user:%s", userID)
+	key := cachekey.User(userID).String()
 	data, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -174,13 +209,13 @@ func (c *Cache) GetCachedUser(userID string) (*domain.User, error) {
 
 // CacheUserByEmail 缓存用户邮箱映射
 func (c *Cache) CacheUserByEmail(email, userID string, ttl time.Duration) error {
-	key := fmt.Sprintf("user:email:%s", email)
+	key := cachekey.UserEmail(email).String()
 	return c.client.Set(c.ctx, key, userID, ttl).Err()
 }
 
 // GetCachedUserByEmail 获取缓存的用户邮箱映射
 func (c *Cache) GetCachedUserByEmail(email string) (string, error) {
-	key := fmt.Sprintf("user:email:%s", email)
+	key := cachekey.UserEmail(email).String()
 	userID, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -195,7 +230,7 @@ func (c *Cache) GetCachedUserByEmail(email string) (string, error) {
 
 // CacheAPIKey 缓存API Key信息
 func (c *Cache) CacheAPIKey(apiKey *domain.APIKey, ttl time.Duration) error {
-	key := fmt.Sprintf("apikey:%s", apiKey.ID)
+	key := cachekey.APIKey(apiKey.ID).String()
 	data, err := json.Marshal(apiKey)
 	if err != nil {
 		return err
@@ -205,7 +240,7 @@ func (c *Cache) CacheAPIKey(apiKey *domain.APIKey, ttl time.Duration) error {
 
 // GetCachedAPIKey 获取缓存的API Key信息
 func (c *Cache) GetCachedAPIKey(apiKeyID string) (*domain.APIKey, error) {
-	key := fmt.Sprintf("apikey:%s", apiKeyID)
+	key := cachekey.APIKey(apiKeyID).String()
 	data, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -224,13 +259,13 @@ func (c *Cache) GetCachedAPIKey(apiKeyID string) (*domain.APIKey, error) {
 
 // CacheAPIKeyUser 缓存API Key到用户ID的映射
 func (c *Cache) CacheAPIKeyUser(apiKey, userID string, ttl time.Duration) error {
-	key := fmt.Sprintf("apikey:user:%s", apiKey)
+	key := cachekey.APIKeyUser(apiKey).String()
 	return c.client.Set(c.ctx, key, userID, ttl).Err()
 }
 
 // GetCachedAPIKeyUser 获取缓存的API Key用户映射
 func (c *Cache) GetCachedAPIKeyUser(apiKey string) (string, error) {
-	key := fmt.Sprintf("apikey:user:%s", apiKey)
+	key := cachekey.APIKeyUser(apiKey).String()
 	userID, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -245,7 +280,7 @@ func (c *Cache) GetCachedAPIKeyUser(apiKey string) (string, error) {
 
 // CacheSystemDomain 缓存系统域名信息
 func (c *Cache) CacheSystemDomain(sysDomain *domain.SystemDomain, ttl time.Duration) error {
-	key := fmt.Sprintf("system_domain:%s", sysDomain.ID)
+	key := cachekey.SystemDomain(sysDomain.ID).String()
 	data, err := json.Marshal(sysDomain)
 	if err != nil {
 		return err
@@ -255,7 +290,7 @@ func (c *Cache) CacheSystemDomain(sysDomain *domain.SystemDomain, ttl time.Durat
 
 // GetCachedSystemDomain 获取缓存的系统域名信息
 func (c *Cache) GetCachedSystemDomain(domainID string) (*domain.SystemDomain, error) {
-	key := fmt.Sprintf("system_domain:%s", domainID)
+	key := cachekey.SystemDomain(domainID).String()
 	data, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -274,7 +309,7 @@ func (c *Cache) GetCachedSystemDomain(domainID string) (*domain.SystemDomain, er
 
 // CacheSystemDomainList 缓存系统域名列表
 func (c *Cache) CacheSystemDomainList(sysDomains []*domain.SystemDomain, ttl time.Duration) error {
-	key := "system_domains:list"
+	key := cachekey.SystemDomainList().String()
 	data, err := json.Marshal(sysDomains)
 	if err != nil {
 		return err
@@ -284,7 +319,7 @@ func (c *Cache) CacheSystemDomainList(sysDomains []*domain.SystemDomain, ttl tim
 
 // GetCachedSystemDomainList 获取缓存的系统域名列表
 func (c *Cache) GetCachedSystemDomainList() ([]*domain.SystemDomain, error) {
-	key := "system_domains:list"
+	key := cachekey.SystemDomainList().String()
 	data, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -303,7 +338,7 @@ func (c *Cache) GetCachedSystemDomainList() ([]*domain.SystemDomain, error) {
 
 // CacheDefaultSystemDomain 缓存默认系统域名
 func (c *Cache) CacheDefaultSystemDomain(sysDomain *domain.SystemDomain, ttl time.Duration) error {
-	key := "system_domain:default"
+	key := cachekey.DefaultSystemDomain().String()
 	data, err := json.Marshal(sysDomain)
 	if err != nil {
 		return err
@@ -313,7 +348,7 @@ func (c *Cache) CacheDefaultSystemDomain(sysDomain *domain.SystemDomain, ttl tim
 
 // GetCachedDefaultSystemDomain 获取缓存的默认系统域名
 func (c *Cache) GetCachedDefaultSystemDomain() (*domain.SystemDomain, error) {
-	key := "system_domain:default"
+	key := cachekey.DefaultSystemDomain().String()
 	data, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -333,15 +368,15 @@ func (c *Cache) GetCachedDefaultSystemDomain() (*domain.SystemDomain, error) {
 // ========== JWT 黑名单 ==========
 
 // AddToBlacklist 将 JWT 添加到黑名单
-func (c *Cache) AddToBlacklist(jti string, ttl time.Duration) error {
-	key := fmt.Sprintf("blacklist:%s", jti)
-	return c.client.Set(c.ctx, key, "1", ttl).Err()
+func (c *Cache) AddToBlacklist(ctx context.Context, jti string, ttl time.Duration) error {
+	key := cachekey.Blacklist(jti).String()
+	return c.client.Set(ctx, key, "1", ttl).Err()
 }
 
 // IsBlacklisted 检查 JWT 是否在黑名单中
-func (c *Cache) IsBlacklisted(jti string) (bool, error) {
-	key := fmt.Sprintf("blacklist:%s", jti)
-	_, err := c.client.Get(c.ctx, key).Result()
+func (c *Cache) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
+	key := cachekey.Blacklist(jti).String()
+	_, err := c.client.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return false, nil
@@ -354,16 +389,16 @@ func (c *Cache) IsBlacklisted(jti string) (bool, error) {
 // ========== 限流缓存 ==========
 
 // IncrementRateLimit 增加限流计数
-func (c *Cache) IncrementRateLimit(key string, window time.Duration) (int64, error) {
+func (c *Cache) IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error) {
 	pipe := c.client.Pipeline()
 
 	// 增加计数
-	incr := pipe.Incr(c.ctx, key)
+	incr := pipe.Incr(ctx, key)
 
 	// 设置过期时间（如果是新键）
-	pipe.Expire(c.ctx, key, window)
+	pipe.Expire(ctx, key, window)
 
-	_, err := pipe.Exec(c.ctx)
+	_, err := pipe.Exec(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -371,9 +406,14 @@ func (c *Cache) IncrementRateLimit(key string, window time.Duration) (int64, err
 	return incr.Val(), nil
 }
 
+// ResetRateLimit 清零限流计数
+func (c *Cache) ResetRateLimit(ctx context.Context, key string) error {
+	return c.client.Del(ctx, key).Err()
+}
+
 // GetRateLimit 获取限流计数
-func (c *Cache) GetRateLimit(key string) (int64, error) {
-	count, err := c.client.Get(c.ctx, key).Int64()
+func (c *Cache) GetRateLimit(ctx context.Context, key string) (int64, error) {
+	count, err := c.client.Get(ctx, key).Int64()
 	if err != nil {
 		if err == redis.Nil {
 			return 0, nil
@@ -386,15 +426,15 @@ func (c *Cache) GetRateLimit(key string) (int64, error) {
 // ========== 会话缓存 ==========
 
 // CacheSession 缓存用户会话
-func (c *Cache) CacheSession(sessionID string, userID string, ttl time.Duration) error {
-	key := fmt.Sprintf("session:%s", sessionID)
-	return c.client.Set(c.ctx, key, userID, ttl).Err()
+func (c *Cache) CacheSession(ctx context.Context, sessionID string, userID string, ttl time.Duration) error {
+	key := cachekey.Session(sessionID).String()
+	return c.client.Set(ctx, key, userID, ttl).Err()
 }
 
 // GetCachedSession 获取缓存的会话
-func (c *Cache) GetCachedSession(sessionID string) (string, error) {
-	key := fmt.Sprintf("session:%s", sessionID)
-	userID, err := c.client.Get(c.ctx, key).Result()
+func (c *Cache) GetCachedSession(ctx context.Context, sessionID string) (string, error) {
+	key := cachekey.Session(sessionID).String()
+	userID, err := c.client.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return "", fmt.Errorf("session not found in cache")
@@ -408,7 +448,7 @@ func (c *Cache) GetCachedSession(sessionID string) (string, error) {
 
 // CacheConfig 缓存系统配置
 func (c *Cache) CacheConfig(config *domain.SystemConfig, ttl time.Duration) error {
-	key := "system:config"
+	key := cachekey.SystemConfig().String()
 	data, err := json.Marshal(config)
 	if err != nil {
 		return err
@@ -418,7 +458,7 @@ func (c *Cache) CacheConfig(config *domain.SystemConfig, ttl time.Duration) erro
 
 // GetCachedConfig 获取缓存的系统配置
 func (c *Cache) GetCachedConfig() (*domain.SystemConfig, error) {
-	key := "system:config"
+	key := cachekey.SystemConfig().String()
 	data, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -437,7 +477,7 @@ func (c *Cache) GetCachedConfig() (*domain.SystemConfig, error) {
 
 // CacheStatistics 缓存系统统计信息
 func (c *Cache) CacheStatistics(stats *domain.SystemStatistics, ttl time.Duration) error {
-	key := "system:statistics"
+	key := cachekey.SystemStatistics().String()
 	data, err := json.Marshal(stats)
 	if err != nil {
 		return err
@@ -447,7 +487,7 @@ func (c *Cache) CacheStatistics(stats *domain.SystemStatistics, ttl time.Duratio
 
 // GetCachedStatistics 获取缓存的系统统计信息
 func (c *Cache) GetCachedStatistics() (*domain.SystemStatistics, error) {
-	key := "system:statistics"
+	key := cachekey.SystemStatistics().String()
 	data, err := c.client.Get(c.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -464,28 +504,121 @@ func (c *Cache) GetCachedStatistics() (*domain.SystemStatistics, error) {
 	return &stats, nil
 }
 
+// cachedMessageStats 邮件统计缓存格式（ByMailbox 不参与 API 序列化，需单独保存）
+type cachedMessageStats struct {
+	*domain.MessageStats
+	ByMailbox map[string]int `json:"byMailbox"`
+}
+
+// CacheMessageStats 缓存邮件统计结果
+func (c *Cache) CacheMessageStats(key string, stats *domain.MessageStats, ttl time.Duration) error {
+	data, err := json.Marshal(cachedMessageStats{MessageStats: stats, ByMailbox: stats.ByMailbox})
+	if err != nil {
+		return err
+	}
+	return c.client.Set(c.ctx, cachekey.MessageStats(key).String(), data, ttl).Err()
+}
+
+// GetCachedMessageStats 获取缓存的邮件统计结果
+func (c *Cache) GetCachedMessageStats(key string) (*domain.MessageStats, error) {
+	data, err := c.client.Get(c.ctx, cachekey.MessageStats(key).String()).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("message stats not found in cache")
+		}
+		return nil, err
+	}
+
+	cached := cachedMessageStats{MessageStats: &domain.MessageStats{}}
+	if err := json.Unmarshal([]byte(data), &cached); err != nil {
+		return nil, err
+	}
+	cached.MessageStats.ByMailbox = cached.ByMailbox
+	return cached.MessageStats, nil
+}
+
 // DeleteCachedSession 删除缓存的会话
-func (c *Cache) DeleteCachedSession(sessionID string) error {
-	key := fmt.Sprintf("session:%s", sessionID)
-	return c.client.Del(c.ctx, key).Err()
+func (c *Cache) DeleteCachedSession(ctx context.Context, sessionID string) error {
+	key := cachekey.Session(sessionID).String()
+	return c.client.Del(ctx, key).Err()
 }
 
 // ========== 发布订阅 ==========
 
+// newMailChannelPrefix 新邮件频道前缀，每个邮箱一个频道
+const newMailChannelPrefix = "new_mail:"
+
 // PublishNewMail 发布新邮件通知
-func (c *Cache) PublishNewMail(mailboxID string, message *domain.Message) error {
-	channel := fmt.Sprintf("new_mail:%s", mailboxID)
+func (c *Cache) PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error {
+	channel := newMailChannelPrefix + mailboxID
 	data, err := json.Marshal(message)
 	if err != nil {
 		return err
 	}
-	return c.client.Publish(c.ctx, channel, data).Err()
+	return c.client.Publish(ctx, channel, data).Err()
 }
 
-// SubscribeNewMail 订阅新邮件通知
-func (c *Cache) SubscribeNewMail(mailboxID string) *redis.PubSub {
-	channel := fmt.Sprintf("new_mail:%s", mailboxID)
-	return c.client.Subscribe(c.ctx, channel)
+// SubscribeNewMail 按模式订阅所有邮箱的新邮件频道
+//
+// 订阅确认后才返回；连接中断时客户端自动重连并重新订阅，期间发布的通知会丢失。ctx 取消后关闭通道。
+func (c *Cache) SubscribeNewMail(ctx context.Context) (<-chan *domain.Message, error) {
+	pubsub := c.client.PSubscribe(ctx, newMailChannelPrefix+"*")
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	messages := make(chan *domain.Message, 64)
+	go func() {
+		defer close(messages)
+		defer pubsub.Close()
+		incoming := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-incoming:
+				if !ok {
+					return
+				}
+				var message domain.Message
+				if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+					continue
+				}
+				if message.MailboxID == "" {
+					message.MailboxID = strings.TrimPrefix(msg.Channel, newMailChannelPrefix)
+				}
+				select {
+				case messages <- &message:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return messages, nil
+}
+
+// ========== 负缓存 ==========
+
+// negativeSentinel 负缓存哨兵值（非 JSON，不可能是正常缓存数据）
+const negativeSentinel = "\x00notfound"
+
+// MarkNotFound 记录“查询结果不存在”
+func (c *Cache) MarkNotFound(key cachekey.Key, ttl time.Duration) error {
+	return c.client.Set(c.ctx, cachekey.NotFound(key).String(), negativeSentinel, ttl).Err()
+}
+
+// IsNotFound 检查键是否处于负缓存中（只返回布尔值，哨兵不会作为数据返回）
+func (c *Cache) IsNotFound(key cachekey.Key) (bool, error) {
+	data, err := c.client.Get(c.ctx, cachekey.NotFound(key).String()).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+		return false, err
+	}
+	return data == negativeSentinel, nil
 }
 
 // ========== 工具方法 ==========
@@ -495,9 +628,16 @@ func (c *Cache) SetTTL(key string, ttl time.Duration) error {
 	return c.client.Expire(c.ctx, key, ttl).Err()
 }
 
-// Delete 删除键
-func (c *Cache) Delete(key string) error {
-	return c.client.Del(c.ctx, key).Err()
+// Delete 删除一组缓存键（一次往返）
+func (c *Cache) Delete(keys ...cachekey.Key) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	return c.client.Del(c.ctx, names...).Err()
 }
 
 // Exists 检查键是否存在
@@ -514,6 +654,11 @@ func (c *Cache) FlushAll() error {
 	return c.client.FlushAll(c.ctx).Err()
 }
 
+// Ping 检查 Redis 连接是否可用
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
 // Close 关闭 Redis 连接
 func (c *Cache) Close() error {
 	return c.client.Close()
@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ========== 分布式锁 ==========
+//
+// 锁保存在 lock:{name} 键中，值为持有者令牌。只有持有者能续期和释放（Lua 脚本比较令牌后操作），
+// 持有者异常退出时锁在 TTL 后自动过期。
+
+// refreshLockScript 令牌匹配时重置过期时间
+var refreshLockScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseLockScript 令牌匹配时删除锁
+var releaseLockScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Locker 基于 Redis 的分布式锁，多个实例共享同一组锁
+type Locker struct {
+	client *goredis.Client
+}
+
+// NewLocker 创建分布式锁
+func NewLocker(client *goredis.Client) *Locker {
+	return &Locker{client: client}
+}
+
+func lockKey(name string) string {
+	return "lock:" + name
+}
+
+// Acquire 尝试获取锁，已被其他持有者占用时返回 false
+func (l *Locker) Acquire(ctx context.Context, name, token string, ttl time.Duration) (bool, error) {
+	return l.client.SetNX(ctx, lockKey(name), token, ttl).Result()
+}
+
+// Refresh 续期锁，锁已过期或被其他持有者占用时返回 false
+func (l *Locker) Refresh(ctx context.Context, name, token string, ttl time.Duration) (bool, error) {
+	n, err := refreshLockScript.Run(ctx, l.client, []string{lockKey(name)}, token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Release 释放锁，锁已不属于 token 时不做任何操作
+func (l *Locker) Release(ctx context.Context, name, token string) error {
+	return releaseLockScript.Run(ctx, l.client, []string{lockKey(name)}, token).Err()
+}
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"tempmail/backend/internal/domain"
+)
+
+// ========== 黑洞模式统计 ==========
+//
+// 计数保存在 sink:{domain}:totals 哈希中；发件人只写入 HyperLogLog（不可还原出地址）；
+// 小时桶为独立的哈希键，过了保留期自动过期。多个实例共享同一组计数。
+
+func sinkKey(domainName, suffix string) string {
+	return fmt.Sprintf("sink:%s:%s", strings.ToLower(domainName), suffix)
+}
+
+func sinkHourKey(domainName string, hour time.Time) string {
+	return sinkKey(domainName, "hour:"+strconv.FormatInt(hour.Unix(), 10))
+}
+
+// RecordSinkMessage 累计一封黑洞邮件，返回累计后的邮件数
+func (c *Cache) RecordSinkMessage(ctx context.Context, domainName, sender string, size int64, at time.Time) (int64, error) {
+	totals := sinkKey(domainName, "totals")
+	hour := at.UTC().Truncate(time.Hour)
+	hourKey := sinkHourKey(domainName, hour)
+
+	pipe := c.client.TxPipeline()
+	messages := pipe.HIncrBy(ctx, totals, "messages", 1)
+	pipe.HIncrBy(ctx, totals, "bytes", size)
+	pipe.HSet(ctx, totals, "last", at.UTC().Unix())
+	pipe.PFAdd(ctx, sinkKey(domainName, "senders"), strings.ToLower(sender))
+	pipe.HIncrBy(ctx, hourKey, "messages", 1)
+	pipe.HIncrBy(ctx, hourKey, "bytes", size)
+	pipe.Expire(ctx, hourKey, domain.SinkStatsRetention+time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return messages.Val(), nil
+}
+
+// RecordSinkSample 累计一封抽样邮件
+func (c *Cache) RecordSinkSample(ctx context.Context, domainName string) error {
+	return c.client.HIncrBy(ctx, sinkKey(domainName, "totals"), "sampled", 1).Err()
+}
+
+// GetSinkStats 获取黑洞模式统计
+func (c *Cache) GetSinkStats(ctx context.Context, domainName string) (*domain.SinkStats, error) {
+	now := time.Now().UTC().Truncate(time.Hour)
+	hours := int(domain.SinkStatsRetention / time.Hour)
+
+	pipe := c.client.Pipeline()
+	totals := pipe.HGetAll(ctx, sinkKey(domainName, "totals"))
+	senders := pipe.PFCount(ctx, sinkKey(domainName, "senders"))
+	buckets := make([]*redis.MapStringStringCmd, hours+1)
+	for i := range buckets {
+		buckets[i] = pipe.HGetAll(ctx, sinkHourKey(domainName, now.Add(-time.Duration(hours-i)*time.Hour)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	stats := &domain.SinkStats{Domain: strings.ToLower(domainName), Hourly: []domain.SinkHourlyCount{}}
+	values := totals.Val()
+	stats.Messages, _ = strconv.ParseInt(values["messages"], 10, 64)
+	stats.Bytes, _ = strconv.ParseInt(values["bytes"], 10, 64)
+	stats.Sampled, _ = strconv.ParseInt(values["sampled"], 10, 64)
+	if last, err := strconv.ParseInt(values["last"], 10, 64); err == nil && last > 0 {
+		lastAt := time.Unix(last, 0).UTC()
+		stats.LastReceivedAt = &lastAt
+	}
+	stats.UniqueSenders = senders.Val()
+
+	for i, cmd := range buckets {
+		bucket := cmd.Val()
+		if len(bucket) == 0 {
+			continue
+		}
+		count := domain.SinkHourlyCount{Hour: now.Add(-time.Duration(hours-i) * time.Hour)}
+		count.Messages, _ = strconv.ParseInt(bucket["messages"], 10, 64)
+		count.Bytes, _ = strconv.ParseInt(bucket["bytes"], 10, 64)
+		stats.Hourly = append(stats.Hourly, count)
+	}
+	return stats, nil
+}
@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -8,7 +9,7 @@ import (
 )
 
 // SearchMessages 搜索邮件（SQL 实现）
-func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error) {
+func (s *Store) SearchMessages(ctx context.Context, criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error) {
 	// 设置默认分页参数
 	if criteria.Page <= 0 {
 		criteria.Page = 1
@@ -81,7 +82,7 @@ func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.M
 	// 获取总数
 	countQuery := "SELECT COUNT(*) FROM messages " + whereClause
 	var total int
-	if err := s.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
 		return nil, fmt.Errorf("failed to count messages: %w", err)
 	}
 
@@ -90,7 +91,7 @@ func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.M
 	query := fmt.Sprintf("SELECT id, mailbox_id, from_address, to_address, subject, text_content, html_content, raw_content, is_read, created_at FROM messages %s ORDER BY created_at DESC LIMIT ? OFFSET ?", whereClause)
 	args = append(args, criteria.PageSize, offset)
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search messages: %w", err)
 	}
@@ -127,10 +128,10 @@ func (s *Store) SearchMessages(criteria domain.MessageSearchCriteria) (*domain.M
 // ========== Webhook Repository ==========
 
 // CreateWebhook 创建 Webhook
-func (s *Store) CreateWebhook(webhook *domain.Webhook) error {
+func (s *Store) CreateWebhook(ctx context.Context, webhook *domain.Webhook) error {
 	query := `INSERT INTO webhooks (id, user_id, url, events, secret, is_active, retry_count, created_at, updated_at) 
 			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.Exec(query,
+	_, err := s.db.ExecContext(ctx, query,
 		webhook.ID,
 		webhook.UserID,
 		webhook.URL,
@@ -145,7 +146,7 @@ func (s *Store) CreateWebhook(webhook *domain.Webhook) error {
 }
 
 // GetWebhook 获取 Webhook
-func (s *Store) GetWebhook(id string) (*domain.Webhook, error) {
+func (s *Store) GetWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
 	query := `SELECT id, user_id, url, events, secret, is_active, retry_count, last_error, last_success, created_at, updated_at 
 			  FROM webhooks WHERE id = ?`
 	
@@ -153,7 +154,7 @@ func (s *Store) GetWebhook(id string) (*domain.Webhook, error) {
 	var eventsJSON string
 	var lastSuccess sql.NullTime
 	
-	err := s.db.QueryRow(query, id).Scan(
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&webhook.ID,
 		&webhook.UserID,
 		&webhook.URL,
@@ -183,11 +184,11 @@ func (s *Store) GetWebhook(id string) (*domain.Webhook, error) {
 }
 
 // ListWebhooks 列出用户的 Webhooks
-func (s *Store) ListWebhooks(userID string) ([]domain.Webhook, error) {
+func (s *Store) ListWebhooks(ctx context.Context, userID string) ([]domain.Webhook, error) {
 	query := `SELECT id, user_id, url, events, secret, is_active, retry_count, last_error, last_success, created_at, updated_at 
 			  FROM webhooks WHERE user_id = ? ORDER BY created_at DESC`
 	
-	rows, err := s.db.Query(query, userID)
+	rows, err := s.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -227,10 +228,10 @@ func (s *Store) ListWebhooks(userID string) ([]domain.Webhook, error) {
 }
 
 // UpdateWebhook 更新 Webhook
-func (s *Store) UpdateWebhook(webhook *domain.Webhook) error {
+func (s *Store) UpdateWebhook(ctx context.Context, webhook *domain.Webhook) error {
 	query := `UPDATE webhooks SET url = ?, events = ?, is_active = ?, retry_count = ?, last_error = ?, last_success = ?, updated_at = ? 
 			  WHERE id = ?`
-	_, err := s.db.Exec(query,
+	_, err := s.db.ExecContext(ctx, query,
 		webhook.URL,
 		eventsToJSON(webhook.Events),
 		webhook.IsActive,
@@ -244,9 +245,9 @@ func (s *Store) UpdateWebhook(webhook *domain.Webhook) error {
 }
 
 // DeleteWebhook 删除 Webhook
-func (s *Store) DeleteWebhook(id string) error {
+func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
 	// 使用事务删除Webhook及其投递记录
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -266,10 +267,10 @@ func (s *Store) DeleteWebhook(id string) error {
 }
 
 // RecordDelivery 记录投递
-func (s *Store) RecordDelivery(delivery *domain.WebhookDelivery) error {
+func (s *Store) RecordDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
 	query := `INSERT INTO webhook_deliveries (id, webhook_id, event, payload, status_code, response, duration, success, error, attempts, next_retry, created_at) 
 			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.Exec(query,
+	_, err := s.db.ExecContext(ctx, query,
 		delivery.ID,
 		delivery.WebhookID,
 		delivery.Event,
@@ -287,11 +288,11 @@ func (s *Store) RecordDelivery(delivery *domain.WebhookDelivery) error {
 }
 
 // GetDeliveries 获取投递记录
-func (s *Store) GetDeliveries(webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+func (s *Store) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
 	query := `SELECT id, webhook_id, event, payload, status_code, response, duration, success, error, attempts, next_retry, created_at 
 			  FROM webhook_deliveries WHERE webhook_id = ? ORDER BY created_at DESC LIMIT ?`
 	
-	rows, err := s.db.Query(query, webhookID, limit)
+	rows, err := s.db.QueryContext(ctx, query, webhookID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -330,14 +331,14 @@ func (s *Store) GetDeliveries(webhookID string, limit int) ([]domain.WebhookDeli
 }
 
 // GetPendingDeliveries 获取待重试投递
-func (s *Store) GetPendingDeliveries(limit int) ([]domain.WebhookDelivery, error) {
+func (s *Store) GetPendingDeliveries(ctx context.Context, limit int) ([]domain.WebhookDelivery, error) {
 	query := `SELECT id, webhook_id, event, payload, status_code, response, duration, success, error, attempts, next_retry, created_at 
 			  FROM webhook_deliveries 
 			  WHERE success = 0 AND next_retry IS NOT NULL AND next_retry <= datetime('now') 
 			  ORDER BY next_retry ASC 
 			  LIMIT ?`
 	
-	rows, err := s.db.Query(query, limit)
+	rows, err := s.db.QueryContext(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"context"
 	"time"
 
 	"tempmail/backend/internal/domain"
@@ -9,7 +10,7 @@ import (
 // ========== System Domain Repository ==========
 
 // SaveSystemDomain 保存系统域名
-func (s *Store) SaveSystemDomain(sysDomain *domain.SystemDomain) error {
+func (s *Store) SaveSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
 	query := `
 		INSERT INTO system_domains (id, domain, is_active, is_default, created_at, mailbox_count, notes)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
@@ -18,7 +19,7 @@ func (s *Store) SaveSystemDomain(sysDomain *domain.SystemDomain) error {
 			is_default = VALUES(is_default),
 			notes = VALUES(notes)
 	`
-	_, err := s.db.Exec(query,
+	_, err := s.db.ExecContext(ctx, query,
 		sysDomain.ID,
 		sysDomain.Domain,
 		sysDomain.IsActive,
@@ -31,14 +32,14 @@ func (s *Store) SaveSystemDomain(sysDomain *domain.SystemDomain) error {
 }
 
 // GetSystemDomain 根据ID获取系统域名
-func (s *Store) GetSystemDomain(domainID string) (*domain.SystemDomain, error) {
+func (s *Store) GetSystemDomain(ctx context.Context, domainID string) (*domain.SystemDomain, error) {
 	query := `
 		SELECT id, domain, is_active, is_default, created_at, mailbox_count, notes
 		FROM system_domains
 		WHERE id = ?
 	`
 	var sysDomain domain.SystemDomain
-	err := s.db.QueryRow(query, domainID).Scan(
+	err := s.db.QueryRowContext(ctx, query, domainID).Scan(
 		&sysDomain.ID,
 		&sysDomain.Domain,
 		&sysDomain.IsActive,
@@ -54,14 +55,14 @@ func (s *Store) GetSystemDomain(domainID string) (*domain.SystemDomain, error) {
 }
 
 // GetSystemDomainByDomain 根据域名获取系统域名
-func (s *Store) GetSystemDomainByDomain(domainName string) (*domain.SystemDomain, error) {
+func (s *Store) GetSystemDomainByDomain(ctx context.Context, domainName string) (*domain.SystemDomain, error) {
 	query := `
 		SELECT id, domain, is_active, is_default, created_at, mailbox_count, notes
 		FROM system_domains
 		WHERE domain = ?
 	`
 	var sysDomain domain.SystemDomain
-	err := s.db.QueryRow(query, domainName).Scan(
+	err := s.db.QueryRowContext(ctx, query, domainName).Scan(
 		&sysDomain.ID,
 		&sysDomain.Domain,
 		&sysDomain.IsActive,
@@ -77,13 +78,13 @@ func (s *Store) GetSystemDomainByDomain(domainName string) (*domain.SystemDomain
 }
 
 // ListSystemDomains 获取所有系统域名
-func (s *Store) ListSystemDomains() ([]*domain.SystemDomain, error) {
+func (s *Store) ListSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
 	query := `
 		SELECT id, domain, is_active, is_default, created_at, mailbox_count, notes
 		FROM system_domains
 		ORDER BY is_default DESC, domain ASC
 	`
-	rows, err := s.db.Query(query)
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -110,14 +111,14 @@ func (s *Store) ListSystemDomains() ([]*domain.SystemDomain, error) {
 }
 
 // ListActiveSystemDomains 获取所有已激活的系统域名
-func (s *Store) ListActiveSystemDomains() ([]*domain.SystemDomain, error) {
+func (s *Store) ListActiveSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error) {
 	query := `
 		SELECT id, domain, is_active, is_default, created_at, mailbox_count, notes
 		FROM system_domains
 		WHERE is_active = true
 		ORDER BY is_default DESC, domain ASC
 	`
-	rows, err := s.db.Query(query)
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -144,13 +145,13 @@ func (s *Store) ListActiveSystemDomains() ([]*domain.SystemDomain, error) {
 }
 
 // UpdateSystemDomain 更新系统域名
-func (s *Store) UpdateSystemDomain(sysDomain *domain.SystemDomain) error {
+func (s *Store) UpdateSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error {
 	query := `
 		UPDATE system_domains
 		SET domain = ?, is_active = ?, is_default = ?, notes = ?
 		WHERE id = ?
 	`
-	_, err := s.db.Exec(query,
+	_, err := s.db.ExecContext(ctx, query,
 		sysDomain.Domain,
 		sysDomain.IsActive,
 		sysDomain.IsDefault,
@@ -161,9 +162,9 @@ func (s *Store) UpdateSystemDomain(sysDomain *domain.SystemDomain) error {
 }
 
 // DeleteSystemDomain 删除系统域名
-func (s *Store) DeleteSystemDomain(domainID string) error {
+func (s *Store) DeleteSystemDomain(ctx context.Context, domainID string) error {
 	query := `DELETE FROM system_domains WHERE id = ?`
-	_, err := s.db.Exec(query, domainID)
+	_, err := s.db.ExecContext(ctx, query, domainID)
 	return err
 }
 
@@ -216,21 +217,21 @@ func (s *Store) GetDefaultSystemDomain() (*domain.SystemDomain, error) {
 }
 
 // IncrementSystemDomainMailboxCount 增加系统域名邮箱计数
-func (s *Store) IncrementSystemDomainMailboxCount(domainName string) error {
+func (s *Store) IncrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
 	query := `UPDATE system_domains SET mailbox_count = mailbox_count + 1 WHERE domain = ?`
-	_, err := s.db.Exec(query, domainName)
+	_, err := s.db.ExecContext(ctx, query, domainName)
 	return err
 }
 
 // DecrementSystemDomainMailboxCount 减少系统域名邮箱计数
-func (s *Store) DecrementSystemDomainMailboxCount(domainName string) error {
+func (s *Store) DecrementSystemDomainMailboxCount(ctx context.Context, domainName string) error {
 	query := `UPDATE system_domains SET mailbox_count = mailbox_count - 1 WHERE domain = ? AND mailbox_count > 0`
-	_, err := s.db.Exec(query, domainName)
+	_, err := s.db.ExecContext(ctx, query, domainName)
 	return err
 }
 
 // DeleteUnverifiedSystemDomains 删除指定时间前创建且未验证的域名
-func (s *Store) DeleteUnverifiedSystemDomains(before time.Time) (int, error) {
+func (s *Store) DeleteUnverifiedSystemDomains(ctx context.Context, before time.Time) (int, error) {
 	// 注意：系统域名通常不需要验证机制，这里返回0
 	// 如果未来需要实现验证机制，可在system_domains表添加verified字段
 	return 0, nil
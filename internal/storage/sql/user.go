@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
@@ -10,12 +11,12 @@ import (
 // ========== User Repository ==========
 
 // CreateUser 创建新用户
-func (s *Store) CreateUser(user *domain.User) error {
+func (s *Store) CreateUser(ctx context.Context, user *domain.User) error {
 	query := `
 		INSERT INTO users (id, email, username, password_hash, role, tier, is_active, is_email_verified, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := s.db.Exec(query,
+	_, err := s.db.ExecContext(ctx, query,
 		user.ID,
 		user.Email,
 		user.Username,
@@ -31,7 +32,7 @@ func (s *Store) CreateUser(user *domain.User) error {
 }
 
 // GetUserByID 根据ID获取用户
-func (s *Store) GetUserByID(id string) (*domain.User, error) {
+func (s *Store) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
 	query := `
 		SELECT id, email, username, password_hash, role, tier, is_active, is_email_verified, 
 		       created_at, updated_at, last_login_at
@@ -41,7 +42,7 @@ func (s *Store) GetUserByID(id string) (*domain.User, error) {
 	var user domain.User
 	var lastLoginAt sql.NullTime
 
-	err := s.db.QueryRow(query, id).Scan(
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Username,
@@ -67,7 +68,7 @@ func (s *Store) GetUserByID(id string) (*domain.User, error) {
 }
 
 // GetUserByEmail 根据邮箱获取用户
-func (s *Store) GetUserByEmail(email string) (*domain.User, error) {
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
 	query := `
 		SELECT id, email, username, password_hash, role, tier, is_active, is_email_verified, 
 		       created_at, updated_at, last_login_at
@@ -77,7 +78,7 @@ func (s *Store) GetUserByEmail(email string) (*domain.User, error) {
 	var user domain.User
 	var lastLoginAt sql.NullTime
 
-	err := s.db.QueryRow(query, email).Scan(
+	err := s.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Username,
@@ -103,7 +104,7 @@ func (s *Store) GetUserByEmail(email string) (*domain.User, error) {
 }
 
 // GetUserByUsername 根据用户名获取用户
-func (s *Store) GetUserByUsername(username string) (*domain.User, error) {
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
 	query := `
 		SELECT id, email, username, password_hash, role, tier, is_active, is_email_verified, 
 		       created_at, updated_at, last_login_at
@@ -113,7 +114,7 @@ func (s *Store) GetUserByUsername(username string) (*domain.User, error) {
 	var user domain.User
 	var lastLoginAt sql.NullTime
 
-	err := s.db.QueryRow(query, username).Scan(
+	err := s.db.QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Username,
@@ -139,14 +140,14 @@ func (s *Store) GetUserByUsername(username string) (*domain.User, error) {
 }
 
 // UpdateUser 更新用户信息
-func (s *Store) UpdateUser(user *domain.User) error {
+func (s *Store) UpdateUser(ctx context.Context, user *domain.User) error {
 	query := `
 		UPDATE users
 		SET email = ?, username = ?, password_hash = ?, role = ?, tier = ?, 
 		    is_active = ?, is_email_verified = ?, updated_at = ?
 		WHERE id = ?
 	`
-	_, err := s.db.Exec(query,
+	_, err := s.db.ExecContext(ctx, query,
 		user.Email,
 		user.Username,
 		user.PasswordHash,
@@ -161,14 +162,14 @@ func (s *Store) UpdateUser(user *domain.User) error {
 }
 
 // UpdateLastLogin 更新用户最后登录时间
-func (s *Store) UpdateLastLogin(userID string) error {
+func (s *Store) UpdateLastLogin(ctx context.Context, userID string) error {
 	query := `UPDATE users SET last_login_at = ? WHERE id = ?`
-	_, err := s.db.Exec(query, time.Now(), userID)
+	_, err := s.db.ExecContext(ctx, query, time.Now(), userID)
 	return err
 }
 
 // ListUsers 列出用户（支持分页和过滤）
-func (s *Store) ListUsers(page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) {
+func (s *Store) ListUsers(ctx context.Context, page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error) {
 	// 构建查询条件
 	where := "WHERE 1=1"
 	args := []interface{}{}
@@ -197,7 +198,7 @@ func (s *Store) ListUsers(page, pageSize int, search string, role *domain.UserRo
 	// 获取总数
 	countQuery := "SELECT COUNT(*) FROM users " + where
 	var total int
-	err := s.db.QueryRow(countQuery, args...).Scan(&total)
+	err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -214,7 +215,7 @@ func (s *Store) ListUsers(page, pageSize int, search string, role *domain.UserRo
 	`
 
 	args = append(args, pageSize, offset)
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -253,14 +254,14 @@ func (s *Store) ListUsers(page, pageSize int, search string, role *domain.UserRo
 }
 
 // DeleteUser 删除用户
-func (s *Store) DeleteUser(userID string) error {
+func (s *Store) DeleteUser(ctx context.Context, userID string) error {
 	query := `DELETE FROM users WHERE id = ?`
-	_, err := s.db.Exec(query, userID)
+	_, err := s.db.ExecContext(ctx, query, userID)
 	return err
 }
 
 // GetUserByAPIKey 根据API Key获取用户
-func (s *Store) GetUserByAPIKey(apiKey string) (*domain.User, error) {
+func (s *Store) GetUserByAPIKey(ctx context.Context, apiKey string) (*domain.User, error) {
 	query := `
 		SELECT u.id, u.email, u.username, u.password_hash, u.role, u.tier, 
 		       u.is_active, u.is_email_verified, u.created_at, u.updated_at, u.last_login_at
@@ -271,7 +272,7 @@ func (s *Store) GetUserByAPIKey(apiKey string) (*domain.User, error) {
 	var user domain.User
 	var lastLoginAt sql.NullTime
 
-	err := s.db.QueryRow(query, apiKey).Scan(
+	err := s.db.QueryRowContext(ctx, query, apiKey).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Username,
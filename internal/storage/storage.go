@@ -1,165 +1,468 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"tempmail/backend/internal/domain"
 	"time"
 )
 
 var (
+	// ErrMailboxNotFound 邮箱未找到错误（各存储实现共用，便于业务层判断）
+	ErrMailboxNotFound = errors.New("mailbox not found")
 	// ErrAttachmentNotFound 附件未找到错误
 	ErrAttachmentNotFound = errors.New("attachment not found")
 	// ErrAliasNotFound 别名未找到错误
 	ErrAliasNotFound = errors.New("alias not found")
 	// ErrAliasExists 别名已存在错误
 	ErrAliasExists = errors.New("alias already exists")
+	// ErrOrganizationNotFound 组织未找到错误
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrOrgMemberNotFound 组织成员未找到错误
+	ErrOrgMemberNotFound = errors.New("organization member not found")
+	// ErrOrgInviteNotFound 组织邀请未找到错误
+	ErrOrgInviteNotFound = errors.New("organization invite not found")
+	// ErrDistributionListNotFound 分发列表未找到错误
+	ErrDistributionListNotFound = errors.New("distribution list not found")
+	// ErrDistributionListExists 分发列表地址已存在错误
+	ErrDistributionListExists = errors.New("distribution list address already exists")
+	// ErrRedactionNotFound 脱敏副本未找到错误
+	ErrRedactionNotFound = errors.New("message redaction not found")
+	// ErrAbuseReportNotFound 滥用举报未找到错误
+	ErrAbuseReportNotFound = errors.New("abuse report not found")
+	// ErrMessageShareNotFound 邮件分享链接未找到错误
+	ErrMessageShareNotFound = errors.New("message share not found")
+	// ErrMaintenanceJobNotFound 维护任务未找到错误
+	ErrMaintenanceJobNotFound = errors.New("maintenance job not found")
+	// ErrMaintenanceJobActive 同类型的维护任务正在排队或运行
+	ErrMaintenanceJobActive = errors.New("maintenance job of this type is already active")
+	// ErrAddressTaken 地址已被其他邮箱或别名占用
+	ErrAddressTaken = errors.New("address already taken")
+	// ErrWhitelistEntryNotFound 域名白名单条目未找到错误
+	ErrWhitelistEntryNotFound = errors.New("domain whitelist entry not found")
+	// ErrWhitelistEntryExists 域名白名单中已有该前缀
+	ErrWhitelistEntryExists = errors.New("domain whitelist entry already exists")
+	// ErrReservedPrefixNotFound 保留前缀未找到错误
+	ErrReservedPrefixNotFound = errors.New("reserved prefix not found")
+	// ErrReservedPrefixExists 域名下已有该保留前缀
+	ErrReservedPrefixExists = errors.New("reserved prefix already exists")
+	// ErrForwardNotFound 邮箱转发地址未找到错误
+	ErrForwardNotFound = errors.New("mailbox forward not found")
+	// ErrOAuthIdentityNotFound 第三方登录身份未找到错误
+	ErrOAuthIdentityNotFound = errors.New("oauth identity not found")
+	// ErrOAuthIdentityExists 该第三方账号已关联到用户
+	ErrOAuthIdentityExists = errors.New("oauth identity already exists")
+	// ErrUserSessionNotFound 登录会话未找到（或已被撤销）
+	ErrUserSessionNotFound = errors.New("user session not found")
+	// ErrUserSessionRotated 刷新令牌已被轮换（并发刷新或重放）
+	ErrUserSessionRotated = errors.New("user session refresh token already rotated")
+	// ErrPubSubUnavailable 未接入 Redis，没有跨实例的发布订阅
+	ErrPubSubUnavailable = errors.New("pub/sub unavailable")
 )
 
 // MailboxRepository 定义邮箱数据存取操作。
 type MailboxRepository interface {
-	SaveMailbox(mailbox *domain.Mailbox) error
-	GetMailbox(id string) (*domain.Mailbox, error)
-	GetMailboxByAddress(address string) (*domain.Mailbox, error)
-	ListMailboxes() []domain.Mailbox
-	ListMailboxesByUserID(userID string) []domain.Mailbox // 按用户ID查询邮箱
-	DeleteMailbox(id string) error
-	DeleteExpiredMailboxes() (int, error) // 删除过期邮箱，返回删除数量
+	// CreateMailbox 原子创建新邮箱，地址已被邮箱或别名占用时返回 ErrAddressTaken（不会覆盖已有记录）
+	CreateMailbox(ctx context.Context, mailbox *domain.Mailbox) error
+	SaveMailbox(ctx context.Context, mailbox *domain.Mailbox) error
+	GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error)
+	GetMailboxByAddress(ctx context.Context, address string) (*domain.Mailbox, error)
+	// GetMailboxesByAddresses 批量按地址查询未过期的邮箱（不存在的地址直接忽略）
+	GetMailboxesByAddresses(ctx context.Context, addresses []string) ([]domain.Mailbox, error)
+	ListMailboxes(ctx context.Context) []domain.Mailbox
+	ListMailboxesByUserID(ctx context.Context, userID string) []domain.Mailbox // 按用户ID查询邮箱
+	ListMailboxesByOrgID(ctx context.Context, orgID string) []domain.Mailbox   // 按组织ID查询邮箱
+	// ListMailboxSummariesByUserID 查询用户的邮箱及按邮件实时统计的数量和最近邮件预览（SQL 存储只发一条查询）
+	ListMailboxSummariesByUserID(ctx context.Context, userID string) ([]domain.MailboxSummary, error)
+	// ListMailboxSummariesByOrgID 查询组织邮箱的摘要
+	ListMailboxSummariesByOrgID(ctx context.Context, orgID string) ([]domain.MailboxSummary, error)
+	DeleteMailbox(ctx context.Context, id string) error
+	DeleteExpiredMailboxes(ctx context.Context) (int, error) // 删除过期邮箱，返回删除数量
+	ListExpiredMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error)
+	// TouchMailbox 记录访问时间，并恢复闲置时被缩短的有效期
+	TouchMailbox(ctx context.Context, mailboxID string, at time.Time) error
+	// ListIdleMailboxes 列出 before 之后未被访问、尚未标记闲置且未过期的用户邮箱（不含游客邮箱）
+	ListIdleMailboxes(ctx context.Context, before, now time.Time) ([]domain.Mailbox, error)
+	// MarkMailboxIdle 标记邮箱闲置；shortenTo 非空时同时缩短有效期并保留原始过期时间
+	MarkMailboxIdle(ctx context.Context, mailboxID string, at time.Time, shortenTo *time.Time) error
+	// ExtendMailbox 设置未过期邮箱新的过期时间并清除闲置状态（闲置时缩短前的原值不再恢复）
+	ExtendMailbox(ctx context.Context, mailboxID string, expiresAt time.Time) error
+	// ListPublicMailboxes 列出未过期的公开收件箱（按地址排序）
+	ListPublicMailboxes(ctx context.Context, now time.Time) ([]domain.Mailbox, error)
 }
 
 // MessageRepository 定义邮件数据存取操作。
 type MessageRepository interface {
-	SaveMessage(message *domain.Message) error
-	ListMessages(mailboxID string) ([]domain.Message, error)
-	GetMessage(mailboxID, messageID string) (*domain.Message, error)
-	MarkMessageRead(mailboxID, messageID string) error
-	DeleteMessage(mailboxID, messageID string) error
-	DeleteAllMessages(mailboxID string) (int, error) // 删除邮箱所有消息，返回删除数量
-	SearchMessages(criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error)
+	SaveMessage(ctx context.Context, message *domain.Message) error
+	// SaveMessages 批量保存邮件并更新各邮箱统计（同一事务，任一邮箱不存在时全部失败）
+	SaveMessages(ctx context.Context, messages []*domain.Message) error
+	ListMessages(ctx context.Context, mailboxID string) ([]domain.Message, error)
+	// ListMessagesPage 按条件过滤、排序并分页列出邮件（邮箱不存在时返回 ErrMailboxNotFound 的存储同 ListMessages）
+	ListMessagesPage(ctx context.Context, query domain.MessageListQuery) (*domain.MessagePage, error)
+	GetMessage(ctx context.Context, mailboxID, messageID string) (*domain.Message, error)
+	MarkMessageRead(ctx context.Context, mailboxID, messageID string) error
+	// MarkMessageUnread 将邮件标记为未读（已是未读时返回 ErrMessageNotFound，与 MarkMessageRead 一致）
+	MarkMessageUnread(ctx context.Context, mailboxID, messageID string) error
+	DeleteMessage(ctx context.Context, mailboxID, messageID string) error
+	DeleteAllMessages(ctx context.Context, mailboxID string) (int, error) // 删除邮箱所有消息，返回删除数量
+	// SetMessageExpiry 设置单封邮件的删除时间（nil 表示清除，随邮箱一起过期）
+	SetMessageExpiry(ctx context.Context, mailboxID, messageID string, expiresAt *time.Time) error
+	// SetMessageQuarantined 将邮件移入或移出隔离区
+	SetMessageQuarantined(ctx context.Context, mailboxID, messageID string, quarantined bool) error
+	// DeleteExpiredMessages 删除 ExpiresAt 不晚于 now 的邮件并修正邮箱统计，按邮箱返回删除的邮件
+	DeleteExpiredMessages(ctx context.Context, now time.Time) ([]domain.ExpiredMessages, error)
+	SearchMessages(ctx context.Context, criteria domain.MessageSearchCriteria) (*domain.MessageSearchResult, error)
+	GetMessageStats(ctx context.Context, query domain.MessageStatsQuery) (*domain.MessageStats, error)
 }
 
 // AliasRepository 定义邮箱别名数据存取操作。
 type AliasRepository interface {
-	SaveAlias(alias *domain.MailboxAlias) error
-	GetAlias(aliasID string) (*domain.MailboxAlias, error)
-	GetAliasByAddress(address string) (*domain.MailboxAlias, error)
-	ListAliasesByMailboxID(mailboxID string) ([]*domain.MailboxAlias, error)
-	DeleteAlias(aliasID string) error
+	SaveAlias(ctx context.Context, alias *domain.MailboxAlias) error
+	GetAlias(ctx context.Context, aliasID string) (*domain.MailboxAlias, error)
+	GetAliasByAddress(ctx context.Context, address string) (*domain.MailboxAlias, error)
+	ListAliasesByMailboxID(ctx context.Context, mailboxID string) ([]*domain.MailboxAlias, error)
+	DeleteAlias(ctx context.Context, aliasID string) error
 }
 
 // UserRepository 定义用户数据存取操作。
 type UserRepository interface {
-	CreateUser(user *domain.User) error
-	GetUserByID(id string) (*domain.User, error)
-	GetUserByEmail(email string) (*domain.User, error)
-	GetUserByUsername(username string) (*domain.User, error)
-	GetUserByAPIKey(apiKey string) (*domain.User, error)
-	UpdateUser(user *domain.User) error
-	UpdateLastLogin(userID string) error
+	CreateUser(ctx context.Context, user *domain.User) error
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*domain.User, error)
+	GetUserByAPIKey(ctx context.Context, apiKey string) (*domain.User, error)
+	UpdateUser(ctx context.Context, user *domain.User) error
+	UpdateLastLogin(ctx context.Context, userID string) error
 }
 
 // AdminRepository 定义管理员数据存取操作。
 type AdminRepository interface {
-	ListUsers(page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error)
-	DeleteUser(userID string) error
-	DeleteMailboxesByUserID(userID string) error
-	GetSystemStatistics() (*domain.SystemStatistics, error)
-	GetDomainStatistics(domain string) (mailboxCount, messageCount int, err error)
+	ListUsers(ctx context.Context, page, pageSize int, search string, role *domain.UserRole, tier *domain.UserTier, isActive *bool) ([]domain.User, int, error)
+	DeleteUser(ctx context.Context, userID string) error
+	DeleteMailboxesByUserID(ctx context.Context, userID string) error
+	GetSystemStatistics(ctx context.Context) (*domain.SystemStatistics, error)
+	GetDomainStatistics(ctx context.Context, domain string) (mailboxCount, messageCount int, err error)
 }
 
 // UserDomainRepository 定义用户域名数据存取操作。
 type UserDomainRepository interface {
-	SaveUserDomain(userDomain *domain.UserDomain) error
-	GetUserDomain(domainID string) (*domain.UserDomain, error)
-	GetUserDomainByDomain(domain string) (*domain.UserDomain, error)
-	ListUserDomainsByUserID(userID string) ([]*domain.UserDomain, error)
-	UpdateUserDomain(userDomain *domain.UserDomain) error
-	DeleteUserDomain(domainID string) error
-	IncrementMailboxCount(domain string) error
-	DecrementMailboxCount(domain string) error
+	SaveUserDomain(ctx context.Context, userDomain *domain.UserDomain) error
+	GetUserDomain(ctx context.Context, domainID string) (*domain.UserDomain, error)
+	GetUserDomainByDomain(ctx context.Context, domain string) (*domain.UserDomain, error)
+	ListUserDomainsByUserID(ctx context.Context, userID string) ([]*domain.UserDomain, error)
+	ListUserDomainsByOrgID(ctx context.Context, orgID string) ([]*domain.UserDomain, error)
+	UpdateUserDomain(ctx context.Context, userDomain *domain.UserDomain) error
+	DeleteUserDomain(ctx context.Context, domainID string) error
+	IncrementMailboxCount(ctx context.Context, domain string) error
+	DecrementMailboxCount(ctx context.Context, domain string) error
 }
 
 // SystemDomainRepository 定义系统域名数据存取操作。
 type SystemDomainRepository interface {
-	SaveSystemDomain(sysDomain *domain.SystemDomain) error
-	GetSystemDomain(domainID string) (*domain.SystemDomain, error)
-	GetSystemDomainByDomain(domain string) (*domain.SystemDomain, error)
-	ListSystemDomains() ([]*domain.SystemDomain, error)
-	ListActiveSystemDomains() ([]*domain.SystemDomain, error)
-	DeleteSystemDomain(domainID string) error
-	IncrementSystemDomainMailboxCount(domain string) error
-	DecrementSystemDomainMailboxCount(domain string) error
-	DeleteUnverifiedSystemDomains(before time.Time) (int, error)
+	SaveSystemDomain(ctx context.Context, sysDomain *domain.SystemDomain) error
+	GetSystemDomain(ctx context.Context, domainID string) (*domain.SystemDomain, error)
+	GetSystemDomainByDomain(ctx context.Context, domain string) (*domain.SystemDomain, error)
+	ListSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error)
+	ListActiveSystemDomains(ctx context.Context) ([]*domain.SystemDomain, error)
+	DeleteSystemDomain(ctx context.Context, domainID string) error
+	IncrementSystemDomainMailboxCount(ctx context.Context, domain string) error
+	DecrementSystemDomainMailboxCount(ctx context.Context, domain string) error
+	DeleteUnverifiedSystemDomains(ctx context.Context, before time.Time) (int, error)
 }
 
 // APIKeyRepository 定义API Key数据存取操作。
 type APIKeyRepository interface {
-	SaveAPIKey(apiKey *domain.APIKey) error
-	GetAPIKey(id string) (*domain.APIKey, error)
-	GetAPIKeyByKey(key string) (*domain.APIKey, error)
-	ListAPIKeysByUserID(userID string) ([]*domain.APIKey, error)
-	DeleteAPIKey(id string) error
-	UpdateAPIKeyLastUsed(id string) error
+	SaveAPIKey(ctx context.Context, apiKey *domain.APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error)
+	GetAPIKeyByKey(ctx context.Context, key string) (*domain.APIKey, error)
+	ListAPIKeysByUserID(ctx context.Context, userID string) ([]*domain.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id string) error
+	UpdateAPIKeyLastUsed(ctx context.Context, id string) error
 }
 
 // JWTRepository 定义 JWT 黑名单操作。
 type JWTRepository interface {
-	AddToBlacklist(jti string, ttl time.Duration) error
-	IsBlacklisted(jti string) (bool, error)
+	AddToBlacklist(ctx context.Context, jti string, ttl time.Duration) error
+	IsBlacklisted(ctx context.Context, jti string) (bool, error)
 }
 
 // RateLimitRepository 定义限流操作。
 type RateLimitRepository interface {
-	IncrementRateLimit(key string, window time.Duration) (int64, error)
-	GetRateLimit(key string) (int64, error)
+	IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error)
+	GetRateLimit(ctx context.Context, key string) (int64, error)
+	ResetRateLimit(ctx context.Context, key string) error // 清零计数（如登录成功后清除失败次数）
 }
 
 // SessionRepository 定义会话管理操作。
 type SessionRepository interface {
-	CacheSession(sessionID string, userID string, ttl time.Duration) error
-	GetCachedSession(sessionID string) (string, error)
-	DeleteCachedSession(sessionID string) error
+	CacheSession(ctx context.Context, sessionID string, userID string, ttl time.Duration) error
+	GetCachedSession(ctx context.Context, sessionID string) (string, error)
+	DeleteCachedSession(ctx context.Context, sessionID string) error
 }
 
 // PubSubRepository 定义发布订阅操作。
 type PubSubRepository interface {
-	PublishNewMail(mailboxID string, message *domain.Message) error
-	SubscribeNewMail(mailboxID string) interface{}
+	PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error
+	// SubscribeNewMail 订阅所有邮箱的新邮件通知，ctx 取消后关闭通道；不支持时返回 ErrPubSubUnavailable
+	SubscribeNewMail(ctx context.Context) (<-chan *domain.Message, error)
 }
 
 // WebhookRepository 定义 Webhook 数据存取操作。
 type WebhookRepository interface {
-	CreateWebhook(webhook *domain.Webhook) error
-	GetWebhook(id string) (*domain.Webhook, error)
-	ListWebhooks(userID string) ([]domain.Webhook, error)
-	UpdateWebhook(webhook *domain.Webhook) error
-	DeleteWebhook(id string) error
-	RecordDelivery(delivery *domain.WebhookDelivery) error
-	GetDeliveries(webhookID string, limit int) ([]domain.WebhookDelivery, error)
-	GetPendingDeliveries(limit int) ([]domain.WebhookDelivery, error)
+	CreateWebhook(ctx context.Context, webhook *domain.Webhook) error
+	GetWebhook(ctx context.Context, id string) (*domain.Webhook, error)
+	ListWebhooks(ctx context.Context, userID string) ([]domain.Webhook, error)
+	ListWebhooksByOrgID(ctx context.Context, orgID string) ([]domain.Webhook, error)
+	ListWebhooksByMailbox(ctx context.Context, mailboxID string) ([]domain.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook *domain.Webhook) error
+	DeleteWebhook(ctx context.Context, id string) error
+	RecordDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error)
+	GetPendingDeliveries(ctx context.Context, limit int) ([]domain.WebhookDelivery, error)
+	CancelPendingDeliveries(ctx context.Context, mailboxID string) (int, error) // 取消邮箱的待重试投递
+	GetDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	ListDeadLetters(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) // 未重新投递的死信
 }
 
 // TagRepository 定义标签数据存取操作。
 type TagRepository interface {
-	CreateTag(tag *domain.Tag) error
-	GetTag(id string) (*domain.Tag, error)
-	GetTagByName(userID, name string) (*domain.Tag, error)
-	ListTags(userID string) ([]domain.TagWithCount, error)
-	UpdateTag(tag *domain.Tag) error
-	DeleteTag(id string) error
-	AddMessageTag(messageID, tagID string) error
-	RemoveMessageTag(messageID, tagID string) error
-	GetMessageTags(messageID string) ([]domain.Tag, error)
-	ListMessagesByTag(tagID string) ([]domain.Message, error)
-	DeleteMessageTags(messageID string) error
+	CreateTag(ctx context.Context, tag *domain.Tag) error
+	GetTag(ctx context.Context, id string) (*domain.Tag, error)
+	GetTagByName(ctx context.Context, userID, name string) (*domain.Tag, error)
+	ListTags(ctx context.Context, userID string) ([]domain.TagWithCount, error)
+	ListTagsByOrgID(ctx context.Context, orgID string) ([]domain.TagWithCount, error)
+	UpdateTag(ctx context.Context, tag *domain.Tag) error
+	DeleteTag(ctx context.Context, id string) error
+	AddMessageTag(ctx context.Context, messageID, tagID string) error
+	RemoveMessageTag(ctx context.Context, messageID, tagID string) error
+	GetMessageTags(ctx context.Context, messageID string) ([]domain.Tag, error)
+	ListMessagesByTag(ctx context.Context, tagID string) ([]domain.Message, error)
+	DeleteMessageTags(ctx context.Context, messageID string) error
+}
+
+// OrganizationRepository 定义组织、成员与邀请数据存取操作。
+type OrganizationRepository interface {
+	CreateOrganization(ctx context.Context, org *domain.Organization) error
+	GetOrganization(ctx context.Context, id string) (*domain.Organization, error)
+	SaveOrgMember(ctx context.Context, member *domain.OrgMember) error
+	GetOrgMember(ctx context.Context, orgID, userID string) (*domain.OrgMember, error)
+	ListOrgMembers(ctx context.Context, orgID string) ([]*domain.OrgMember, error)
+	ListOrgMembershipsByUserID(ctx context.Context, userID string) ([]*domain.OrgMember, error)
+	DeleteOrgMember(ctx context.Context, orgID, userID string) error
+	SaveOrgInvite(ctx context.Context, invite *domain.OrgInvite) error
+	GetOrgInvite(ctx context.Context, token string) (*domain.OrgInvite, error)
+	DeleteOrgInvite(ctx context.Context, token string) error
+}
+
+// DistributionListRepository 定义分发列表及投递报告数据存取操作。
+type DistributionListRepository interface {
+	SaveDistributionList(ctx context.Context, list *domain.DistributionList) error
+	GetDistributionList(ctx context.Context, id string) (*domain.DistributionList, error)
+	GetDistributionListByAddress(ctx context.Context, address string) (*domain.DistributionList, error)
+	ListDistributionListsByUserID(ctx context.Context, userID string) ([]*domain.DistributionList, error)
+	ListDistributionListsByOrgID(ctx context.Context, orgID string) ([]*domain.DistributionList, error)
+	DeleteDistributionList(ctx context.Context, id string) error
+	SaveDistributionListDelivery(ctx context.Context, delivery *domain.DistributionListDelivery) error
+	ListDistributionListDeliveries(ctx context.Context, listID string, limit int) ([]*domain.DistributionListDelivery, error)
+}
+
+// RedactionRepository 定义邮件脱敏副本数据存取操作。
+type RedactionRepository interface {
+	SaveMessageRedaction(ctx context.Context, redaction *domain.MessageRedaction) error
+	GetMessageRedaction(ctx context.Context, mailboxID, messageID string) (*domain.MessageRedaction, error)
+}
+
+// AbuseReportRepository 定义滥用举报数据存取操作。
+type AbuseReportRepository interface {
+	SaveAbuseReport(ctx context.Context, report *domain.AbuseReport) error
+	GetAbuseReport(ctx context.Context, id string) (*domain.AbuseReport, error)
+	// ListAbuseReports 按过滤条件列出举报（按创建时间倒序）
+	ListAbuseReports(ctx context.Context, filter domain.AbuseReportFilter) ([]*domain.AbuseReport, error)
+}
+
+// MessageShareRepository 定义邮件分享链接数据存取操作。
+type MessageShareRepository interface {
+	SaveMessageShare(ctx context.Context, share *domain.MessageShare) error
+	GetMessageShare(ctx context.Context, id string) (*domain.MessageShare, error)
+	// ListMessageShares 列出邮箱的分享链接（按创建时间倒序）
+	ListMessageShares(ctx context.Context, mailboxID string) ([]*domain.MessageShare, error)
+	// RecordMessageShareView 访问次数加一并记录访问时间（原子操作）
+	RecordMessageShareView(ctx context.Context, id string, at time.Time) error
+}
+
+// DomainWhitelistRepository 定义用户域名白名单数据存取操作。
+type DomainWhitelistRepository interface {
+	// AddDomainWhitelistEntry 添加白名单条目，域名下已有相同前缀时返回 ErrWhitelistEntryExists
+	AddDomainWhitelistEntry(ctx context.Context, entry *domain.DomainWhitelistEntry) error
+	// ListDomainWhitelist 列出域名的白名单（按前缀排序）
+	ListDomainWhitelist(ctx context.Context, domainID string) ([]*domain.DomainWhitelistEntry, error)
+	// DeleteDomainWhitelistEntry 删除域名下的白名单条目，不存在时返回 ErrWhitelistEntryNotFound
+	DeleteDomainWhitelistEntry(ctx context.Context, domainID, id string) error
+	// IsLocalPartWhitelisted 前缀是否在域名白名单中
+	IsLocalPartWhitelisted(ctx context.Context, domainID, localPart string) (bool, error)
+}
+
+// ReservedPrefixRepository 定义系统域名保留前缀数据存取操作。
+type ReservedPrefixRepository interface {
+	// CreateReservedPrefix 添加保留前缀，同一域名下已有相同前缀时返回 ErrReservedPrefixExists
+	CreateReservedPrefix(ctx context.Context, prefix *domain.ReservedPrefix) error
+	// ListReservedPrefixes 列出保留前缀（按域名、前缀排序），userID 非空时只列出该用户预留的前缀
+	ListReservedPrefixes(ctx context.Context, userID *string) ([]*domain.ReservedPrefix, error)
+	// FindReservedPrefixes 查找前缀在指定域名及所有系统域名（Domain 为空）上的条目（含已过期）
+	FindReservedPrefixes(ctx context.Context, domainName, localPart string) ([]*domain.ReservedPrefix, error)
+	// DeleteReservedPrefix 删除保留前缀，不存在时返回 ErrReservedPrefixNotFound
+	DeleteReservedPrefix(ctx context.Context, id string) error
+}
+
+// OAuthIdentityRepository 定义第三方登录身份数据存取操作。
+type OAuthIdentityRepository interface {
+	// CreateOAuthIdentity 关联第三方登录身份，同一提供方的账号已关联时返回 ErrOAuthIdentityExists
+	CreateOAuthIdentity(ctx context.Context, identity *domain.OAuthIdentity) error
+	// GetOAuthIdentity 按提供方和提供方用户标识查找，不存在时返回 ErrOAuthIdentityNotFound
+	GetOAuthIdentity(ctx context.Context, provider, subject string) (*domain.OAuthIdentity, error)
+	// TouchOAuthIdentity 更新身份的最近登录时间
+	TouchOAuthIdentity(ctx context.Context, id string, at time.Time) error
+	// ListOAuthIdentities 列出用户关联的第三方登录身份（按关联时间排序）
+	ListOAuthIdentities(ctx context.Context, userID string) ([]*domain.OAuthIdentity, error)
+}
+
+// UserSessionRepository 定义登录会话数据存取操作。
+type UserSessionRepository interface {
+	CreateUserSession(ctx context.Context, session *domain.UserSession) error
+	// GetUserSession 不存在时返回 ErrUserSessionNotFound
+	GetUserSession(ctx context.Context, id string) (*domain.UserSession, error)
+	// RotateUserSession 仅当会话当前的刷新令牌 jti 仍为 previousRefreshID 时保存轮换后的会话，
+	// 否则返回 ErrUserSessionRotated（会话不存在时返回 ErrUserSessionNotFound）
+	RotateUserSession(ctx context.Context, session *domain.UserSession, previousRefreshID string) error
+	// ListUserSessions 列出用户的会话（按最近使用时间倒序，含已过期的）
+	ListUserSessions(ctx context.Context, userID string) ([]*domain.UserSession, error)
+	DeleteUserSession(ctx context.Context, id string) error
+	// DeleteExpiredUserSessions 删除 ExpiresAt 不晚于 now 的会话，返回删除数量
+	DeleteExpiredUserSessions(ctx context.Context, now time.Time) (int64, error)
+}
+
+// AuditLogRepository 定义管理操作审计记录存取操作（只追加）。
+type AuditLogRepository interface {
+	CreateAuditLog(ctx context.Context, log *domain.AuditLog) error
+	// ListAuditLogs 按过滤条件分页列出审计记录（按时间倒序），同时返回符合条件的总数
+	ListAuditLogs(ctx context.Context, filter domain.AuditLogFilter) ([]*domain.AuditLog, int64, error)
+}
+
+// OutboxRepository 定义事务性发件箱存取操作。
+type OutboxRepository interface {
+	// SaveMessagesWithOutbox 在同一事务内保存邮件（语义同 SaveMessages）和它们的发件箱事件
+	SaveMessagesWithOutbox(ctx context.Context, messages []*domain.Message, events []*domain.OutboxEvent) error
+	// ClaimOutboxEvents 认领最多 limit 条在 now 时刻可认领的事件（按创建时间排序），认领到 until 为止；
+	// 每条事件同一时刻只会被一个调用方认领
+	ClaimOutboxEvents(ctx context.Context, now, until time.Time, limit int) ([]*domain.OutboxEvent, error)
+	// UpdateOutboxEvent 保存事件的投递进度（Delivered、Attempts、LastError、NextAttemptAt、ClaimedUntil、ProcessedAt）
+	UpdateOutboxEvent(ctx context.Context, event *domain.OutboxEvent) error
+	// DeleteProcessedOutboxEvents 删除 ProcessedAt 早于 before 的事件，返回删除数
+	DeleteProcessedOutboxEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SentMessageRepository 定义已发送邮件数据存取操作。
+type SentMessageRepository interface {
+	SaveSentMessage(ctx context.Context, message *domain.SentMessage) error
+	// ListSentMessages 列出邮箱发出的邮件（按发送时间倒序）
+	ListSentMessages(ctx context.Context, mailboxID string) ([]*domain.SentMessage, error)
+	// CountSentMessages 按邮箱或用户统计 Since 之后发出的邮件数（用于发信配额）
+	CountSentMessages(ctx context.Context, filter domain.SentMessageFilter) (domain.SentMessageUsage, error)
+}
+
+// ForwardRepository 定义邮箱转发地址及其投递队列的数据存取操作。
+type ForwardRepository interface {
+	// SaveMailboxForward 保存转发地址（新建或更新）
+	SaveMailboxForward(ctx context.Context, forward *domain.MailboxForward) error
+	// GetMailboxForward 获取邮箱下的转发地址，不存在时返回 ErrForwardNotFound
+	GetMailboxForward(ctx context.Context, mailboxID, id string) (*domain.MailboxForward, error)
+	// ListMailboxForwards 列出邮箱的转发地址（按创建时间排序）
+	ListMailboxForwards(ctx context.Context, mailboxID string) ([]*domain.MailboxForward, error)
+	// DeleteMailboxForward 删除邮箱下的转发地址及其未完成的投递，不存在时返回 ErrForwardNotFound
+	DeleteMailboxForward(ctx context.Context, mailboxID, id string) error
+	// SaveForwardDelivery 保存投递记录（新建或更新）
+	SaveForwardDelivery(ctx context.Context, delivery *domain.ForwardDelivery) error
+	// ListPendingForwardDeliveries 列出到期待投递的记录（NextRetry 不晚于 now，按 NextRetry 排序）
+	ListPendingForwardDeliveries(ctx context.Context, now time.Time, limit int) ([]*domain.ForwardDelivery, error)
+}
+
+// SearchIndexRepository 定义邮件全文索引的数据存取操作。
+//
+// 索引文档随邮件一起删除；查询只返回仍存在且不在隔离区的邮件。
+type SearchIndexRepository interface {
+	// IndexMessages 写入或覆盖邮件的索引文档
+	IndexMessages(ctx context.Context, docs []*domain.SearchDocument) error
+	// SearchIndex 查找命中全部词项的邮件，按接收时间倒序分页
+	SearchIndex(ctx context.Context, query domain.IndexQuery) (*domain.MessageSearchResult, error)
+	// ClearSearchIndex 清空索引（重建前调用）
+	ClearSearchIndex(ctx context.Context) error
+}
+
+// MaintenanceJobRepository 定义维护任务数据存取操作。
+type MaintenanceJobRepository interface {
+	// CreateMaintenanceJob 创建任务，同类型已有排队或运行中的任务时返回 ErrMaintenanceJobActive
+	CreateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error
+	GetMaintenanceJob(ctx context.Context, id string) (*domain.MaintenanceJob, error)
+	// ListMaintenanceJobs 列出任务（按创建时间倒序）
+	ListMaintenanceJobs(ctx context.Context) ([]*domain.MaintenanceJob, error)
+	// UpdateMaintenanceJob 保存任务状态和进度，任务结束后释放同类型的占用
+	UpdateMaintenanceJob(ctx context.Context, job *domain.MaintenanceJob) error
+}
+
+// MaintenanceScanRepository 定义维护任务按主键顺序分批扫描和修正数据的操作。
+type MaintenanceScanRepository interface {
+	// CountMessages 统计全部邮件数
+	CountMessages(ctx context.Context) (int64, error)
+	// ListMessagesAfter 按 ID 升序列出 ID 大于 afterID 的邮件（跨邮箱，含隔离区）
+	ListMessagesAfter(ctx context.Context, afterID string, limit int) ([]domain.Message, error)
+	// SetMessageDerivedText 记录邮件已有由 HTML 生成的纯文本（HasText、TextDerivedFromHTML 置为 true）
+	SetMessageDerivedText(ctx context.Context, mailboxID, messageID, text string) error
+	// CountMailboxes 统计全部邮箱数（含已过期未清理的）
+	CountMailboxes(ctx context.Context) (int64, error)
+	// ListMailboxIDsAfter 按 ID 升序列出 ID 大于 afterID 的邮箱 ID
+	ListMailboxIDsAfter(ctx context.Context, afterID string, limit int) ([]string, error)
+	// RecountMailbox 按邮件重新统计邮箱的总数和未读数，返回统计是否有变化（邮箱已删除时为 false）
+	RecountMailbox(ctx context.Context, mailboxID string) (bool, error)
+}
+
+// AnalyticsRepository 定义使用情况统计（汇总计数）存取操作。
+type AnalyticsRepository interface {
+	// MergeAnalyticsBuckets 合并计数：峰值类指标取最大值，其余累加（不存在时插入）
+	MergeAnalyticsBuckets(ctx context.Context, buckets []domain.AnalyticsBucket) error
+	// ListAnalyticsBuckets 列出指标在 [since, until) 内的时间桶（按时间升序）
+	ListAnalyticsBuckets(ctx context.Context, metric string, since, until time.Time) ([]domain.AnalyticsBucket, error)
+	// DeleteAnalyticsBucketsBefore 删除早于 before 的时间桶，返回删除数
+	DeleteAnalyticsBucketsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// MailFlowRepository 定义收信流量计数（按分钟）存取操作。
+type MailFlowRepository interface {
+	// MergeMailFlowCounters 累加计数（不存在时插入）
+	MergeMailFlowCounters(ctx context.Context, counters []domain.MailFlowCounter) error
+	// ListMailFlowCounters 列出 [since, until) 内的计数（按时间升序）
+	ListMailFlowCounters(ctx context.Context, since, until time.Time) ([]domain.MailFlowCounter, error)
+	// DeleteMailFlowCountersBefore 删除早于 before 的计数，返回删除数
+	DeleteMailFlowCountersBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SinkStatsRepository 定义黑洞模式汇总统计操作。
+type SinkStatsRepository interface {
+	RecordSinkMessage(ctx context.Context, domainName, sender string, size int64, at time.Time) (int64, error)
+	RecordSinkSample(ctx context.Context, domainName string) error
+	GetSinkStats(ctx context.Context, domainName string) (*domain.SinkStats, error)
 }
 
 // SystemConfigRepository 定义系统配置数据存取操作。
 type SystemConfigRepository interface {
-	GetSystemConfig() (*domain.SystemConfig, error)
-	SaveSystemConfig(config *domain.SystemConfig) error
+	GetSystemConfig(ctx context.Context) (*domain.SystemConfig, error)
+	SaveSystemConfig(ctx context.Context, config *domain.SystemConfig) error
 }
 
 // Store 定义完整的存储接口。
+//
+// 除 Close、Health 外的方法都以调用方的 ctx 为第一个参数：请求取消、截止时间和链路信息
+// 随 ctx 传到数据库和 Redis 调用；SQL 和 MongoDB 实现另加单次查询超时（见各自的 timeout.go）。
 type Store interface {
 	MailboxRepository
 	MessageRepository
@@ -171,6 +474,25 @@ type Store interface {
 	APIKeyRepository
 	WebhookRepository
 	TagRepository
+	OrganizationRepository
+	DistributionListRepository
+	RedactionRepository
+	AbuseReportRepository
+	MessageShareRepository
+	SentMessageRepository
+	DomainWhitelistRepository
+	ReservedPrefixRepository
+	OAuthIdentityRepository
+	UserSessionRepository
+	AuditLogRepository
+	OutboxRepository
+	ForwardRepository
+	SearchIndexRepository
+	MaintenanceJobRepository
+	MaintenanceScanRepository
+	AnalyticsRepository
+	MailFlowRepository
+	SinkStatsRepository
 	SystemConfigRepository
 	JWTRepository
 	RateLimitRepository
@@ -182,6 +504,6 @@ type Store interface {
 	Health() error
 
 	// 兼容 domain.Store 接口的方法
-	ListAllUserDomains() ([]*domain.UserDomain, error)
-	UpdateUserDomain(userDomain *domain.UserDomain) error
+	ListAllUserDomains(ctx context.Context) ([]*domain.UserDomain, error)
+	UpdateUserDomain(ctx context.Context, userDomain *domain.UserDomain) error
 }
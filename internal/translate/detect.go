@@ -0,0 +1,238 @@
+package translate
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 语言检测
+//
+// 非拉丁文字按字符集直接判断；拉丁文字使用三元组（trigram）排序距离算法（Cavnar & Trenkle），
+// 语言画像在初始化时由内置样本文本生成，不依赖网络和外部模型。
+
+// detectProfileSize 每种语言画像保留的三元组数量
+const detectProfileSize = 300
+
+// detectMinLetters 检测所需的最少字母数（过短的文本不做判断）
+const detectMinLetters = 12
+
+// detectMaxRunes 检测时最多读取的字符数（检测只需开头一段，避免大邮件开销）
+const detectMaxRunes = 4000
+
+// latinSamples 拉丁文字语言样本（常见功能词和邮件常用语）
+var latinSamples = map[string]string{
+	"en": `Thank you for signing up. Please confirm your email address by clicking the link below.
+		If you did not create an account, you can safely ignore this message. Your verification code is
+		valid for the next ten minutes. We will never ask you for your password. The team is here to help
+		you with anything you need. This is an automated message, please do not reply to this email.
+		Click here to reset your password and get back into your account. Welcome to our service and
+		thanks for joining us. If you have any questions about your order, contact our support team.
+		You are receiving this because you have an account with us and we want to keep you informed
+		about the latest changes to our terms and the way that we handle your personal information.`,
+	"de": `Vielen Dank für Ihre Anmeldung. Bitte bestätigen Sie Ihre E-Mail-Adresse, indem Sie auf den
+		folgenden Link klicken. Wenn Sie kein Konto erstellt haben, können Sie diese Nachricht ignorieren.
+		Ihr Bestätigungscode ist für die nächsten zehn Minuten gültig. Wir werden Sie niemals nach Ihrem
+		Passwort fragen. Das Team hilft Ihnen gerne weiter. Dies ist eine automatisch erstellte Nachricht,
+		bitte antworten Sie nicht auf diese E-Mail. Klicken Sie hier, um Ihr Passwort zurückzusetzen und
+		wieder auf Ihr Konto zuzugreifen. Willkommen bei unserem Dienst und danke, dass Sie dabei sind.
+		Wenn Sie Fragen zu Ihrer Bestellung haben, wenden Sie sich an unseren Kundendienst. Sie erhalten
+		diese Nachricht, weil Sie ein Konto bei uns haben und wir Sie über die neuesten Änderungen
+		unserer Bedingungen und den Umgang mit Ihren persönlichen Daten informieren möchten.`,
+	"fr": `Merci de votre inscription. Veuillez confirmer votre adresse e-mail en cliquant sur le lien
+		ci-dessous. Si vous n'avez pas créé de compte, vous pouvez ignorer ce message en toute sécurité.
+		Votre code de vérification est valable pendant les dix prochaines minutes. Nous ne vous
+		demanderons jamais votre mot de passe. L'équipe est là pour vous aider avec tout ce dont vous avez
+		besoin. Ceci est un message automatique, merci de ne pas répondre à cet e-mail. Cliquez ici pour
+		réinitialiser votre mot de passe et accéder de nouveau à votre compte. Bienvenue sur notre service
+		et merci de nous avoir rejoints. Si vous avez des questions sur votre commande, contactez notre
+		équipe d'assistance. Vous recevez ce message parce que vous avez un compte chez nous et que nous
+		souhaitons vous informer des dernières modifications de nos conditions et de la manière dont nous
+		traitons vos données personnelles.`,
+	"es": `Gracias por registrarte. Por favor confirma tu dirección de correo electrónico haciendo clic en
+		el enlace de abajo. Si no creaste una cuenta, puedes ignorar este mensaje con seguridad. Tu código
+		de verificación es válido durante los próximos diez minutos. Nunca te pediremos tu contraseña. El
+		equipo está aquí para ayudarte con todo lo que necesites. Este es un mensaje automático, por favor
+		no respondas a este correo. Haz clic aquí para restablecer tu contraseña y volver a acceder a tu
+		cuenta. Bienvenido a nuestro servicio y gracias por unirte. Si tienes alguna pregunta sobre tu
+		pedido, ponte en contacto con nuestro equipo de soporte. Recibes este mensaje porque tienes una
+		cuenta con nosotros y queremos informarte de los últimos cambios en nuestras condiciones y de la
+		forma en que tratamos tus datos personales.`,
+	"it": `Grazie per esserti registrato. Conferma il tuo indirizzo email facendo clic sul link qui sotto.
+		Se non hai creato un account, puoi ignorare questo messaggio in tutta sicurezza. Il tuo codice di
+		verifica è valido per i prossimi dieci minuti. Non ti chiederemo mai la tua password. Il team è qui
+		per aiutarti con tutto ciò di cui hai bisogno. Questo è un messaggio automatico, per favore non
+		rispondere a questa email. Fai clic qui per reimpostare la password e accedere di nuovo al tuo
+		account. Benvenuto nel nostro servizio e grazie per esserti unito a noi. Se hai domande sul tuo
+		ordine, contatta il nostro team di assistenza. Ricevi questo messaggio perché hai un account presso
+		di noi e vogliamo informarti sulle ultime modifiche alle nostre condizioni e sul modo in cui
+		trattiamo i tuoi dati personali.`,
+	"pt": `Obrigado por se cadastrar. Por favor, confirme o seu endereço de e-mail clicando no link abaixo.
+		Se você não criou uma conta, pode ignorar esta mensagem com segurança. O seu código de verificação
+		é válido pelos próximos dez minutos. Nunca pediremos a sua senha. A equipe está aqui para ajudar
+		você com tudo o que precisar. Esta é uma mensagem automática, por favor não responda a este e-mail.
+		Clique aqui para redefinir a sua senha e voltar a acessar a sua conta. Bem-vindo ao nosso serviço
+		e obrigado por se juntar a nós. Se tiver alguma dúvida sobre o seu pedido, entre em contato com a
+		nossa equipe de suporte. Você está recebendo esta mensagem porque tem uma conta conosco e queremos
+		informá-lo sobre as últimas alterações nos nossos termos e na forma como tratamos os seus dados
+		pessoais.`,
+	"nl": `Bedankt voor je aanmelding. Bevestig je e-mailadres door op de onderstaande link te klikken.
+		Als je geen account hebt aangemaakt, kun je dit bericht veilig negeren. Je verificatiecode is de
+		komende tien minuten geldig. We zullen je nooit om je wachtwoord vragen. Het team staat klaar om
+		je te helpen met alles wat je nodig hebt. Dit is een automatisch bericht, gelieve niet te
+		antwoorden op deze e-mail. Klik hier om je wachtwoord opnieuw in te stellen en weer toegang te
+		krijgen tot je account. Welkom bij onze dienst en bedankt dat je erbij bent. Als je vragen hebt
+		over je bestelling, neem dan contact op met ons supportteam. Je ontvangt dit bericht omdat je een
+		account bij ons hebt en we je willen informeren over de laatste wijzigingen in onze voorwaarden en
+		de manier waarop we met je persoonlijke gegevens omgaan.`,
+}
+
+// latinProfiles 拉丁文字语言画像（三元组 -> 排名）
+var latinProfiles = buildProfiles(latinSamples)
+
+// scriptLanguages 按字符集判断的语言
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+}
+
+// Detect 检测文本语言，返回 ISO 639-1 代码；文本过短或无法判断时返回空字符串
+func Detect(text string) string {
+	var latin strings.Builder
+	counts := make(map[string]int)
+	letters, han, kana := 0, 0, 0
+
+	read := 0
+	for _, r := range text {
+		if read >= detectMaxRunes {
+			break
+		}
+		read++
+
+		if !unicode.IsLetter(r) {
+			latin.WriteRune(' ')
+			continue
+		}
+		letters++
+
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+			continue
+		case unicode.Is(unicode.Han, r):
+			han++
+			continue
+		case unicode.Is(unicode.Latin, r):
+			latin.WriteRune(unicode.ToLower(r))
+			counts["latin"]++
+			continue
+		}
+		for _, sl := range scriptLanguages {
+			if unicode.Is(sl.table, r) {
+				counts[sl.lang]++
+				break
+			}
+		}
+	}
+
+	if letters < detectMinLetters && han+kana < detectMinLetters/3 {
+		return ""
+	}
+
+	// 中日文：出现假名判定为日文，否则为中文
+	if han+kana > letters/3 {
+		if kana > 0 && kana*10 >= han {
+			return "ja"
+		}
+		return "zh"
+	}
+
+	best, bestCount := "", 0
+	for lang, count := range counts {
+		if count > bestCount {
+			best, bestCount = lang, count
+		}
+	}
+	if best != "latin" {
+		return best
+	}
+	if counts["latin"] < detectMinLetters {
+		return ""
+	}
+	return detectLatin(latin.String())
+}
+
+// detectLatin 使用三元组排序距离判断拉丁文字语言
+func detectLatin(text string) string {
+	ranked := rankTrigrams(text, detectProfileSize)
+	if len(ranked) == 0 {
+		return ""
+	}
+
+	best, bestDistance := "", -1
+	for lang, profile := range latinProfiles {
+		distance := 0
+		for i, gram := range ranked {
+			if rank, ok := profile[gram]; ok {
+				if rank > i {
+					distance += rank - i
+				} else {
+					distance += i - rank
+				}
+			} else {
+				distance += detectProfileSize
+			}
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = lang, distance
+		}
+	}
+	return best
+}
+
+// buildProfiles 由样本文本生成语言画像
+func buildProfiles(samples map[string]string) map[string]map[string]int {
+	profiles := make(map[string]map[string]int, len(samples))
+	for lang, sample := range samples {
+		ranked := rankTrigrams(strings.ToLower(sample), detectProfileSize)
+		profile := make(map[string]int, len(ranked))
+		for i, gram := range ranked {
+			profile[gram] = i
+		}
+		profiles[lang] = profile
+	}
+	return profiles
+}
+
+// rankTrigrams 统计单词内三元组（含词首尾空格）并按频次排序，返回前 limit 个
+func rankTrigrams(text string, limit int) []string {
+	freq := make(map[string]int)
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			freq[string(runes[i:i+3])]++
+		}
+	}
+
+	grams := make([]string, 0, len(freq))
+	for gram := range freq {
+		grams = append(grams, gram)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if freq[grams[i]] != freq[grams[j]] {
+			return freq[grams[i]] > freq[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > limit {
+		grams = grams[:limit]
+	}
+	return grams
+}
@@ -0,0 +1,294 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"tempmail/backend/internal/config"
+)
+
+// 支持的翻译服务提供方
+const (
+	ProviderNone           = "none"
+	ProviderDeepL          = "deepl"
+	ProviderGoogle         = "google"
+	ProviderLibreTranslate = "libretranslate"
+)
+
+var (
+	ErrNotConfigured   = errors.New("translation provider not configured")
+	ErrUnknownProvider = errors.New("unknown translation provider")
+	ErrMissingEndpoint = errors.New("translation provider endpoint required")
+	ErrMissingAPIKey   = errors.New("translation provider api key required")
+)
+
+// 默认服务地址
+const (
+	defaultDeepLEndpoint  = "https://api-free.deepl.com/v2/translate"
+	defaultGoogleEndpoint = "https://translation.googleapis.com/language/translate/v2"
+)
+
+// maxErrorBody 读取错误响应体的最大字节数
+const maxErrorBody = 1024
+
+// Request 翻译请求
+type Request struct {
+	Text   string // 待翻译纯文本
+	Source string // 源语言（ISO 639-1，留空由服务方自动识别）
+	Target string // 目标语言（ISO 639-1）
+}
+
+// Result 翻译结果
+type Result struct {
+	Text           string // 译文
+	SourceLanguage string // 服务方识别的源语言（可能为空）
+}
+
+// Translator 翻译服务接口
+type Translator interface {
+	Name() string
+	Translate(ctx context.Context, req Request) (*Result, error)
+}
+
+// ProviderError 翻译服务返回的错误
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode > 0 {
+		return fmt.Sprintf("%s: status %d: %s", e.Provider, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Provider, e.Message)
+}
+
+// New 根据配置创建翻译服务，未配置时返回 ErrNotConfigured
+func New(cfg config.TranslateConfig) (Translator, error) {
+	client := &http.Client{Timeout: cfg.Timeout}
+	if client.Timeout <= 0 {
+		client.Timeout = 10 * time.Second
+	}
+
+	var t Translator
+	switch strings.ToLower(cfg.Provider) {
+	case "", ProviderNone:
+		return nil, ErrNotConfigured
+	case ProviderDeepL:
+		if cfg.APIKey == "" {
+			return nil, ErrMissingAPIKey
+		}
+		t = &deepL{endpoint: orDefault(cfg.Endpoint, defaultDeepLEndpoint), apiKey: cfg.APIKey, client: client}
+	case ProviderGoogle:
+		if cfg.APIKey == "" {
+			return nil, ErrMissingAPIKey
+		}
+		t = &google{endpoint: orDefault(cfg.Endpoint, defaultGoogleEndpoint), apiKey: cfg.APIKey, client: client}
+	case ProviderLibreTranslate:
+		if cfg.Endpoint == "" {
+			return nil, ErrMissingEndpoint
+		}
+		t = &libreTranslate{endpoint: strings.TrimRight(cfg.Endpoint, "/") + "/translate", apiKey: cfg.APIKey, client: client}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, cfg.Provider)
+	}
+
+	return WithRateLimit(t, cfg.RateLimit), nil
+}
+
+// WithRateLimit 为翻译服务增加限流（每分钟 perMinute 次，<=0 表示不限流）
+func WithRateLimit(t Translator, perMinute int) Translator {
+	if perMinute <= 0 {
+		return t
+	}
+	return &limited{
+		next:    t,
+		limiter: rate.NewLimiter(rate.Every(time.Minute/time.Duration(perMinute)), perMinute),
+	}
+}
+
+// limited 限流包装
+type limited struct {
+	next    Translator
+	limiter *rate.Limiter
+}
+
+func (l *limited) Name() string { return l.next.Name() }
+
+func (l *limited) Translate(ctx context.Context, req Request) (*Result, error) {
+	if err := l.limiter.Wait(ctx); err != nil {
+		return nil, &ProviderError{Provider: l.next.Name(), Message: "rate limit exceeded"}
+	}
+	return l.next.Translate(ctx, req)
+}
+
+// deepL DeepL API
+type deepL struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+func (d *deepL) Name() string { return ProviderDeepL }
+
+func (d *deepL) Translate(ctx context.Context, req Request) (*Result, error) {
+	form := url.Values{}
+	form.Set("text", req.Text)
+	form.Set("target_lang", strings.ToUpper(req.Target))
+	if req.Source != "" {
+		form.Set("source_lang", strings.ToUpper(req.Source))
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, d.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Authorization", "DeepL-Auth-Key "+d.apiKey)
+
+	var resp struct {
+		Translations []struct {
+			DetectedSourceLanguage string `json:"detected_source_language"`
+			Text                   string `json:"text"`
+		} `json:"translations"`
+		Message string `json:"message"`
+	}
+	if err := doJSON(d.client, httpReq, ProviderDeepL, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Translations) == 0 {
+		return nil, &ProviderError{Provider: ProviderDeepL, Message: "empty response"}
+	}
+	return &Result{
+		Text:           resp.Translations[0].Text,
+		SourceLanguage: strings.ToLower(resp.Translations[0].DetectedSourceLanguage),
+	}, nil
+}
+
+// google Google Cloud Translation v2
+type google struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+func (g *google) Name() string { return ProviderGoogle }
+
+func (g *google) Translate(ctx context.Context, req Request) (*Result, error) {
+	body := map[string]string{"q": req.Text, "target": req.Target, "format": "text"}
+	if req.Source != "" {
+		body["source"] = req.Source
+	}
+
+	httpReq, err := newJSONRequest(ctx, g.endpoint+"?key="+url.QueryEscape(g.apiKey), body)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data struct {
+			Translations []struct {
+				TranslatedText         string `json:"translatedText"`
+				DetectedSourceLanguage string `json:"detectedSourceLanguage"`
+			} `json:"translations"`
+		} `json:"data"`
+	}
+	if err := doJSON(g.client, httpReq, ProviderGoogle, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data.Translations) == 0 {
+		return nil, &ProviderError{Provider: ProviderGoogle, Message: "empty response"}
+	}
+	return &Result{
+		Text:           resp.Data.Translations[0].TranslatedText,
+		SourceLanguage: resp.Data.Translations[0].DetectedSourceLanguage,
+	}, nil
+}
+
+// libreTranslate LibreTranslate（可自建）
+type libreTranslate struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+func (l *libreTranslate) Name() string { return ProviderLibreTranslate }
+
+func (l *libreTranslate) Translate(ctx context.Context, req Request) (*Result, error) {
+	source := req.Source
+	if source == "" {
+		source = "auto"
+	}
+	body := map[string]string{"q": req.Text, "source": source, "target": req.Target, "format": "text"}
+	if l.apiKey != "" {
+		body["api_key"] = l.apiKey
+	}
+
+	httpReq, err := newJSONRequest(ctx, l.endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		TranslatedText   string `json:"translatedText"`
+		DetectedLanguage struct {
+			Language string `json:"language"`
+		} `json:"detectedLanguage"`
+	}
+	if err := doJSON(l.client, httpReq, ProviderLibreTranslate, &resp); err != nil {
+		return nil, err
+	}
+	return &Result{Text: resp.TranslatedText, SourceLanguage: resp.DetectedLanguage.Language}, nil
+}
+
+// newJSONRequest 创建 JSON 请求
+func newJSONRequest(ctx context.Context, endpoint string, body interface{}) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// doJSON 发送请求并解析 JSON 响应，非 2xx 状态转换为 ProviderError
+func doJSON(client *http.Client, req *http.Request, provider string, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return &ProviderError{Provider: provider, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &ProviderError{Provider: provider, StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return &ProviderError{Provider: provider, Message: "invalid response: " + err.Error()}
+	}
+	return nil
+}
+
+// orDefault 空值时返回默认值
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
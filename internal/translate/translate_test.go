@@ -0,0 +1,124 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/security"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestDetect(t *testing.T) {
+	t.Run("英文验证邮件", func(t *testing.T) {
+		assert.Equal(t, "en", Detect(readFixture(t, "verify_en.txt")))
+	})
+
+	t.Run("德文验证邮件", func(t *testing.T) {
+		assert.Equal(t, "de", Detect(readFixture(t, "verify_de.txt")))
+	})
+
+	t.Run("法文 HTML 邮件（提取文本后检测）", func(t *testing.T) {
+		text := security.ExtractText(readFixture(t, "verify_fr.html"))
+		assert.NotContains(t, text, "trackOpen")
+		assert.NotContains(t, text, "color")
+		assert.Equal(t, "fr", Detect(text))
+	})
+
+	t.Run("按字符集判断", func(t *testing.T) {
+		assert.Equal(t, "zh", Detect("您好，您的验证码是 123456，请在十分钟内完成验证。"))
+		assert.Equal(t, "ja", Detect("こんにちは。確認コードは 123456 です。十分以内に入力してください。"))
+		assert.Equal(t, "ru", Detect("Здравствуйте! Ваш код подтверждения действителен десять минут."))
+	})
+
+	t.Run("文本过短不做判断", func(t *testing.T) {
+		assert.Equal(t, "", Detect("OK 123"))
+		assert.Equal(t, "", Detect(""))
+	})
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(config.TranslateConfig{Provider: "none"})
+	assert.ErrorIs(t, err, ErrNotConfigured)
+
+	_, err = New(config.TranslateConfig{})
+	assert.ErrorIs(t, err, ErrNotConfigured)
+
+	_, err = New(config.TranslateConfig{Provider: "deepl"})
+	assert.ErrorIs(t, err, ErrMissingAPIKey)
+
+	_, err = New(config.TranslateConfig{Provider: "libretranslate"})
+	assert.ErrorIs(t, err, ErrMissingEndpoint)
+
+	_, err = New(config.TranslateConfig{Provider: "babelfish"})
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+}
+
+func TestLibreTranslate(t *testing.T) {
+	t.Run("成功翻译", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/translate", r.URL.Path)
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "de", body["source"])
+			assert.Equal(t, "en", body["target"])
+			assert.Equal(t, "secret", body["api_key"])
+			_ = json.NewEncoder(w).Encode(map[string]string{"translatedText": "Hello"})
+		}))
+		defer server.Close()
+
+		tr, err := New(config.TranslateConfig{Provider: "libretranslate", Endpoint: server.URL + "/", APIKey: "secret"})
+		require.NoError(t, err)
+
+		result, err := tr.Translate(context.Background(), Request{Text: "Hallo", Source: "de", Target: "en"})
+		require.NoError(t, err)
+		assert.Equal(t, "Hello", result.Text)
+	})
+
+	t.Run("服务方错误透传", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":"Invalid API key"}`))
+		}))
+		defer server.Close()
+
+		tr, err := New(config.TranslateConfig{Provider: "libretranslate", Endpoint: server.URL})
+		require.NoError(t, err)
+
+		_, err = tr.Translate(context.Background(), Request{Text: "Hallo", Target: "en"})
+		var providerErr *ProviderError
+		require.True(t, errors.As(err, &providerErr))
+		assert.Equal(t, http.StatusForbidden, providerErr.StatusCode)
+		assert.Contains(t, providerErr.Message, "Invalid API key")
+	})
+
+	t.Run("超时", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer server.Close()
+
+		tr, err := New(config.TranslateConfig{Provider: "libretranslate", Endpoint: server.URL, Timeout: 20 * time.Millisecond})
+		require.NoError(t, err)
+
+		_, err = tr.Translate(context.Background(), Request{Text: "Hallo", Target: "en"})
+		var providerErr *ProviderError
+		assert.True(t, errors.As(err, &providerErr))
+	})
+}
@@ -0,0 +1,158 @@
+package grpctransport
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// TokenValidator 用户访问令牌验证（jwt.Manager 实现，含黑名单检查）
+type TokenValidator interface {
+	Authenticate(ctx context.Context, tokenString string) (*jwtpkg.Claims, error)
+}
+
+// MailboxAuthorizer 用户对邮箱的权限（service.Authorizer 实现，按实时组织成员关系判断）
+type MailboxAuthorizer interface {
+	CanAccessMailbox(ctx context.Context, userID string, mailbox *domain.Mailbox, action service.Action) bool
+}
+
+// mailboxRequest 访问单个邮箱的请求（生成的请求类型都有 GetMailboxId）
+type mailboxRequest interface {
+	GetMailboxId() string
+}
+
+// 认证结果在 context 中的键
+type (
+	userIDKey  struct{}
+	tierKey    struct{}
+	mailboxKey struct{}
+)
+
+// userIDFrom 访问令牌对应的用户（未携带或无效时为空）
+func userIDFrom(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey{}).(string)
+	return userID
+}
+
+// tierFrom 访问令牌对应的用户等级
+func tierFrom(ctx context.Context) domain.UserTier {
+	tier, _ := ctx.Value(tierKey{}).(domain.UserTier)
+	return tier
+}
+
+// mailboxFrom 已通过认证的邮箱
+func mailboxFrom(ctx context.Context) *domain.Mailbox {
+	mailbox, _ := ctx.Value(mailboxKey{}).(*domain.Mailbox)
+	return mailbox
+}
+
+// unaryAuth 一元调用的认证拦截器
+func (s *Server) unaryAuth(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamAuth 流式调用的认证拦截器：收到请求消息后认证并计入请求配额，认证结果通过 Context 传给处理函数
+func (s *Server) streamAuth(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &authStream{ServerStream: stream, ctx: stream.Context(), server: s})
+}
+
+// authStream 在第一次 RecvMsg 后完成认证的 ServerStream
+type authStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	server *Server
+}
+
+func (a *authStream) Context() context.Context {
+	return a.ctx
+}
+
+func (a *authStream) RecvMsg(m any) error {
+	if err := a.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	ctx, err := a.server.authenticate(a.ServerStream.Context(), m)
+	if err != nil {
+		return err
+	}
+	if err := a.server.takeQuota(ctx, a.ServerStream.SetHeader); err != nil {
+		return err
+	}
+	a.ctx = ctx
+	return nil
+}
+
+// authenticate 按 metadata 中的凭证认证请求
+//
+// 有效的用户访问令牌记录用户（创建的邮箱归属该用户，无效时按游客处理，与 HTTP 接口一致）；
+// 访问邮箱的请求要求邮箱令牌，或邮箱所有者及组织成员的访问令牌，停用的邮箱拒绝一切访问。
+func (s *Server) authenticate(ctx context.Context, req any) (context.Context, error) {
+	token := credential(ctx)
+	if token != "" && s.tokens != nil {
+		// 代登录令牌只读，gRPC 接口含写操作，不接受
+		if claims, err := s.tokens.Authenticate(ctx, token); err == nil && claims.Impersonator == "" {
+			ctx = context.WithValue(ctx, userIDKey{}, claims.UserID)
+			ctx = context.WithValue(ctx, tierKey{}, domain.UserTier(claims.Tier))
+		}
+	}
+
+	r, ok := req.(mailboxRequest)
+	if !ok {
+		return ctx, nil
+	}
+	mailboxID := r.GetMailboxId()
+	if mailboxID == "" {
+		return nil, status.Error(codes.InvalidArgument, "mailbox ID required")
+	}
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "mailbox token required")
+	}
+	mailbox, err := s.mailboxes.Get(ctx, mailboxID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "mailbox not found")
+	}
+	if mailbox.Token != token && !s.userAllowed(ctx, userIDFrom(ctx), mailbox) {
+		return nil, status.Error(codes.Unauthenticated, "invalid mailbox token")
+	}
+	if mailbox.Suspended {
+		return nil, status.Error(codes.PermissionDenied, "mailbox suspended")
+	}
+	return context.WithValue(ctx, mailboxKey{}, mailbox), nil
+}
+
+// userAllowed 判断用户是否有权访问邮箱
+func (s *Server) userAllowed(ctx context.Context, userID string, mailbox *domain.Mailbox) bool {
+	if userID == "" || s.authz == nil {
+		return false
+	}
+	return s.authz.CanAccessMailbox(ctx, userID, mailbox, service.ActionWrite)
+}
+
+// credential 从 metadata 提取凭证：authorization: Bearer <token>，或 x-mailbox-token
+func credential(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok && token != "" {
+			return token
+		}
+	}
+	if values := md.Get("x-mailbox-token"); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
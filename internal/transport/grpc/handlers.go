@@ -0,0 +1,270 @@
+package grpctransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+	"tempmail/backend/pkg/tempmailpb"
+	"tempmail/backend/pkg/wsproto"
+)
+
+// CreateMailbox 创建临时邮箱（携带访问令牌时归属该用户）
+func (s *Server) CreateMailbox(ctx context.Context, req *tempmailpb.CreateMailboxRequest) (*tempmailpb.Mailbox, error) {
+	input := service.CreateMailboxInput{
+		Prefix:    req.GetPrefix(),
+		Domain:    req.GetDomain(),
+		IPSource:  peerIP(ctx),
+		Public:    req.GetPublic(),
+		AutoRenew: req.GetAutoRenew(),
+	}
+	if userID := userIDFrom(ctx); userID != "" {
+		input.UserID = &userID
+	}
+	if orgID := req.GetOrgId(); orgID != "" {
+		input.OrgID = &orgID
+	}
+	if req.GetExpiresIn() != nil {
+		d := req.GetExpiresIn().AsDuration()
+		if d <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "expires_in must be positive")
+		}
+		expiresAt := time.Now().Add(d)
+		input.ExpiresAt = &expiresAt
+	}
+
+	mailbox, err := s.mailboxes.Create(ctx, input)
+	if err != nil {
+		return nil, createError(err)
+	}
+	return toMailbox(mailbox), nil
+}
+
+// createError 创建邮箱错误对应的状态码（与 HTTP 接口一致）
+func createError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrDomainNotAllowed), errors.Is(err, service.ErrPrefixInvalid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrDomainExpired), errors.Is(err, service.ErrPublicInboxNotAllowed),
+		errors.Is(err, service.ErrAddressNotWhitelisted), errors.Is(err, service.ErrPrefixReserved),
+		errors.Is(err, service.ErrRenewRequiresAccount), errors.Is(err, service.ErrNotOrgMember),
+		errors.Is(err, service.ErrOrgOwnerRequired):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrAddressTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, service.ErrOrgNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrOrgQuotaExceeded), errors.Is(err, service.ErrMailboxQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, "failed to create mailbox")
+	}
+}
+
+// ListMessages 分页列出邮件
+func (s *Server) ListMessages(ctx context.Context, req *tempmailpb.ListMessagesRequest) (*tempmailpb.ListMessagesResponse, error) {
+	page, err := s.messages.ListPage(ctx, service.ListPageInput{
+		MailboxID:  req.GetMailboxId(),
+		UnreadOnly: req.GetUnreadOnly(),
+		Limit:      int(req.GetLimit()),
+		Offset:     int(req.GetOffset()),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMessagePage) || errors.Is(err, service.ErrInvalidMessageSort) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Internal, "failed to list messages")
+	}
+	s.touch(ctx, req.GetMailboxId())
+
+	resp := &tempmailpb.ListMessagesResponse{
+		Messages: make([]*tempmailpb.MessageSummary, 0, len(page.Messages)),
+		Total:    int32(page.Total),
+	}
+	for i := range page.Messages {
+		resp.Messages = append(resp.Messages, toSummary(&page.Messages[i]))
+	}
+	return resp, nil
+}
+
+// GetMessage 获取单封邮件
+func (s *Server) GetMessage(ctx context.Context, req *tempmailpb.GetMessageRequest) (*tempmailpb.Message, error) {
+	if req.GetMessageId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "message ID required")
+	}
+	message, err := s.messages.Get(ctx, req.GetMailboxId(), req.GetMessageId())
+	if err != nil {
+		if errors.Is(err, memory.ErrMessageNotFound) {
+			return nil, status.Error(codes.NotFound, "message not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get message")
+	}
+	s.touch(ctx, req.GetMailboxId())
+
+	resp := &tempmailpb.Message{
+		Summary: toSummary(message),
+		Text:    message.Text,
+		Html:    message.HTML,
+	}
+	if req.GetIncludeRaw() {
+		resp.Raw = []byte(message.Raw)
+	}
+	for _, a := range message.Attachments {
+		resp.Attachments = append(resp.Attachments, &tempmailpb.Attachment{
+			Id:          a.ID,
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+		})
+	}
+	if v := message.Verification; !v.Empty() {
+		resp.Verification = &tempmailpb.Verification{Code: v.Code, Codes: v.Codes, Links: v.Links}
+	}
+	return resp, nil
+}
+
+// StreamMessages 推送新邮件，直到客户端取消、邮箱失效或服务关闭
+func (s *Server) StreamMessages(req *tempmailpb.StreamMessagesRequest, stream tempmailpb.TempMail_StreamMessagesServer) error {
+	if s.hub == nil {
+		return status.Error(codes.Unavailable, "message streaming not enabled")
+	}
+	ctx := stream.Context()
+	events := s.hub.OpenStream(req.GetMailboxId(), userIDFrom(ctx), req.GetSinceSeq())
+	defer events.Close()
+	events.Touch(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.done:
+			return status.Error(codes.Unavailable, "server shutting down")
+		case <-events.Evicted():
+			return status.Error(codes.ResourceExhausted, "client too slow")
+		case payload, ok := <-events.Events():
+			if !ok {
+				return status.Error(codes.Unavailable, "server shutting down")
+			}
+			if err := s.sendEvent(stream, events, payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamEvents StreamMessages 使用的 Hub 事件流
+type streamEvents interface {
+	Closed() (code int, reason string)
+	Touch(ctx context.Context)
+}
+
+// sendEvent 把一条 Hub 消息转换为流事件；返回错误时结束流
+//
+// 只推送新邮件；心跳时检查会话是否已被服务端关闭（邮箱停用、用户停用）并记录访问。
+func (s *Server) sendEvent(stream tempmailpb.TempMail_StreamMessagesServer, events streamEvents, payload []byte) error {
+	var msg wsproto.Message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return nil
+	}
+	switch msg.Type {
+	case wsproto.MessageTypeNewMail:
+		var data wsproto.NewMailData
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return nil
+		}
+		return stream.Send(&tempmailpb.MessageEvent{Message: newMailSummary(&data), Replayed: msg.Replayed})
+	case wsproto.MessageTypePing:
+		if code, reason := events.Closed(); code != 0 {
+			return status.Error(codes.PermissionDenied, reason)
+		}
+		events.Touch(stream.Context())
+	case wsproto.MessageTypeMailboxDeleted:
+		return status.Error(codes.NotFound, "mailbox deleted")
+	case wsproto.MessageTypeMailboxSuspended:
+		return status.Error(codes.PermissionDenied, "mailbox suspended")
+	case wsproto.MessageTypeError:
+		return status.Error(codes.ResourceExhausted, msg.Error)
+	}
+	return nil
+}
+
+// toMailbox 转换邮箱
+func toMailbox(m *domain.Mailbox) *tempmailpb.Mailbox {
+	mailbox := &tempmailpb.Mailbox{
+		Id:        m.ID,
+		Address:   m.Address,
+		Token:     m.Token,
+		CreatedAt: timestamppb.New(m.CreatedAt),
+		Public:    m.IsPublic,
+		AutoRenew: m.AutoRenew,
+	}
+	if m.ExpiresAt != nil {
+		mailbox.ExpiresAt = timestamppb.New(*m.ExpiresAt)
+	}
+	if m.OrgID != nil {
+		mailbox.OrgId = *m.OrgID
+	}
+	return mailbox
+}
+
+// toSummary 转换邮件元数据
+func toSummary(m *domain.Message) *tempmailpb.MessageSummary {
+	receivedAt := m.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = m.CreatedAt
+	}
+	return &tempmailpb.MessageSummary{
+		Id:         m.ID,
+		MailboxId:  m.MailboxID,
+		Seq:        m.Seq,
+		From:       m.From,
+		To:         m.To,
+		Subject:    m.Subject,
+		ReceivedAt: timestamppb.New(receivedAt),
+		IsRead:     m.IsRead,
+		Size:       m.Size,
+		HasHtml:    m.HasHTML,
+		HasText:    m.HasText,
+	}
+}
+
+// newMailSummary 转换新邮件通知
+func newMailSummary(data *wsproto.NewMailData) *tempmailpb.MessageSummary {
+	summary := &tempmailpb.MessageSummary{
+		Id:        data.MessageID,
+		MailboxId: data.MailboxID,
+		Seq:       data.Seq,
+		From:      data.From,
+		To:        data.To,
+		Subject:   data.Subject,
+		HasHtml:   data.HasHTML,
+		HasText:   data.HasText,
+		Preview:   data.Preview,
+	}
+	if t, err := time.Parse(time.RFC3339, data.CreatedAt); err == nil {
+		summary.ReceivedAt = timestamppb.New(t)
+	}
+	return summary
+}
+
+// peerIP 客户端 IP
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
@@ -0,0 +1,86 @@
+package grpctransport
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/pkg/tempmailpb"
+)
+
+// MaintenanceChecker 维护模式状态（service.ConfigService 实现）
+type MaintenanceChecker interface {
+	MaintenanceState() (readOnly bool, message string)
+}
+
+// RequestQuota 按用户等级的每分钟请求配额（middleware.RequestQuota 实现，与 HTTP 接口共用计数）
+type RequestQuota interface {
+	Take(ctx context.Context, userID string, tier domain.UserTier) (*middleware.QuotaWindow, bool)
+}
+
+// mutatingMethods 维护只读模式下拒绝的写操作
+var mutatingMethods = map[string]bool{
+	tempmailpb.TempMail_CreateMailbox_FullMethodName: true,
+}
+
+// unaryLimits 一元调用的维护模式和请求配额拦截器（在认证之后执行）
+//
+// 维护只读模式下写操作返回 Unavailable；携带有效访问令牌的调用计入用户的每分钟请求配额，
+// 超出时返回 ResourceExhausted，并以 x-ratelimit-* 和 retry-after 响应头告知窗口。
+func (s *Server) unaryLimits(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if mutatingMethods[info.FullMethod] {
+		if err := s.checkMaintenance(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.takeQuota(ctx, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) }); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// checkMaintenance 维护只读模式下返回 Unavailable
+func (s *Server) checkMaintenance() error {
+	if s.maintenance == nil {
+		return nil
+	}
+	readOnly, message := s.maintenance.MaintenanceState()
+	if !readOnly {
+		return nil
+	}
+	if message == "" {
+		message = "service temporarily unavailable for maintenance, try again later"
+	}
+	return status.Error(codes.Unavailable, message)
+}
+
+// takeQuota 计入用户的一次请求（游客和邮箱令牌访问不计入），通过 setHeader 输出配额窗口
+func (s *Server) takeQuota(ctx context.Context, setHeader func(metadata.MD) error) error {
+	userID := userIDFrom(ctx)
+	if s.quota == nil || userID == "" {
+		return nil
+	}
+	window, ok := s.quota.Take(ctx, userID, tierFrom(ctx))
+	if window == nil {
+		return nil
+	}
+	md := metadata.Pairs(
+		"x-ratelimit-limit", strconv.Itoa(window.Limit),
+		"x-ratelimit-remaining", strconv.Itoa(max(window.Remaining, 0)),
+		"x-ratelimit-reset", strconv.FormatInt(window.Reset.Unix(), 10),
+	)
+	if ok {
+		_ = setHeader(md)
+		return nil
+	}
+	md.Set("retry-after", strconv.Itoa(middleware.RetryAfterSeconds(time.Until(window.Reset))))
+	_ = setHeader(md)
+	return status.Error(codes.ResourceExhausted, "api request quota exceeded for account tier")
+}
@@ -0,0 +1,165 @@
+// Package grpctransport tempmail gRPC API（pkg/tempmailpb）
+//
+// 面向高并发的测试工具：创建邮箱、分页列出和读取邮件，以及以服务端流推送新邮件（与 WebSocket、SSE
+// 共用 Hub 的订阅和补发）。认证由拦截器完成，规则与 HTTP 接口的邮箱令牌认证一致，见 auth.go；
+// 维护只读模式和按用户等级的每分钟请求配额同样由拦截器执行，见 limits.go。
+package grpctransport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/websocket"
+	"tempmail/backend/pkg/tempmailpb"
+)
+
+// DefaultAddr 默认监听地址
+const DefaultAddr = ":9090"
+
+// ErrServerClosed 服务已关闭，Serve/ListenAndServe 在 Shutdown 后返回
+var ErrServerClosed = errors.New("grpc: server closed")
+
+// MailboxService 邮箱创建和查询（service.MailboxService 实现）
+type MailboxService interface {
+	Create(ctx context.Context, input service.CreateMailboxInput) (*domain.Mailbox, error)
+	Get(ctx context.Context, id string) (*domain.Mailbox, error)
+}
+
+// MessageService 邮件查询（service.MessageService 实现）
+type MessageService interface {
+	ListPage(ctx context.Context, input service.ListPageInput) (*domain.MessagePage, error)
+	Get(ctx context.Context, mailboxID, messageID string) (*domain.Message, error)
+}
+
+// ActivityRecorder 记录邮箱访问（闲置检测，service.MailboxIdleService 实现）
+type ActivityRecorder interface {
+	Touch(ctx context.Context, mailboxID string) error
+}
+
+// Server gRPC 服务
+type Server struct {
+	tempmailpb.UnimplementedTempMailServer
+
+	Addr string // 监听地址，为空时使用 DefaultAddr
+
+	mailboxes   MailboxService
+	messages    MessageService
+	hub         *websocket.Hub     // 新邮件推送（可选，未设置时 StreamMessages 不可用）
+	tokens      TokenValidator     // 用户访问令牌验证（可选）
+	authz       MailboxAuthorizer  // 用户对邮箱的权限（与 tokens 一起设置）
+	activity    ActivityRecorder   // 邮箱访问记录（可选）
+	maintenance MaintenanceChecker // 维护只读模式（可选）
+	quota       RequestQuota       // 用户每分钟请求配额（可选）
+	log         *zap.Logger
+
+	server    *grpc.Server
+	closeOnce sync.Once
+	done      chan struct{} // Shutdown 时关闭，结束进行中的流
+}
+
+// NewServer 创建 gRPC 服务
+func NewServer(mailboxes MailboxService, messages MessageService, log *zap.Logger) *Server {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	s := &Server{
+		Addr:      DefaultAddr,
+		mailboxes: mailboxes,
+		messages:  messages,
+		log:       log,
+		done:      make(chan struct{}),
+	}
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryAuth, s.unaryLimits),
+		grpc.ChainStreamInterceptor(s.streamAuth),
+	)
+	tempmailpb.RegisterTempMailServer(s.server, s)
+	return s
+}
+
+// SetUserAccess 允许邮箱所有者及所在组织成员使用访问令牌代替邮箱令牌
+func (s *Server) SetUserAccess(tokens TokenValidator, authz MailboxAuthorizer) {
+	s.tokens = tokens
+	s.authz = authz
+}
+
+// SetEventHub 设置新邮件推送来源（StreamMessages）
+func (s *Server) SetEventHub(hub *websocket.Hub) {
+	s.hub = hub
+}
+
+// SetActivityRecorder 设置邮箱访问记录
+func (s *Server) SetActivityRecorder(recorder ActivityRecorder) {
+	s.activity = recorder
+}
+
+// SetMaintenanceChecker 维护只读模式下拒绝写操作（CreateMailbox）
+func (s *Server) SetMaintenanceChecker(checker MaintenanceChecker) {
+	s.maintenance = checker
+}
+
+// SetRequestQuota 以访问令牌调用时按用户等级计入每分钟请求配额（与 HTTP 接口共用计数）
+func (s *Server) SetRequestQuota(quota RequestQuota) {
+	s.quota = quota
+}
+
+// ListenAndServe 监听 Addr 并处理请求，直到 Shutdown
+func (s *Server) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve 在 l 上处理请求，直到 Shutdown
+func (s *Server) Serve(l net.Listener) error {
+	select {
+	case <-s.done:
+		_ = l.Close()
+		return ErrServerClosed
+	default:
+	}
+	if err := s.server.Serve(l); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return ErrServerClosed
+}
+
+// Shutdown 结束进行中的流并等待其他请求完成；ctx 到期时强制关闭连接
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.closeOnce.Do(func() { close(s.done) })
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// touch 记录邮箱访问（失败只记日志）
+func (s *Server) touch(ctx context.Context, mailboxID string) {
+	if s.activity == nil {
+		return
+	}
+	if err := s.activity.Touch(ctx, mailboxID); err != nil {
+		s.log.Warn("failed to record mailbox activity", zap.String("mailboxID", mailboxID), zap.Error(err))
+	}
+}
@@ -0,0 +1,262 @@
+package grpctransport
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+	"tempmail/backend/internal/websocket"
+	"tempmail/backend/pkg/tempmailpb"
+)
+
+type testEnv struct {
+	client   tempmailpb.TempMailClient
+	store    *memory.Store
+	messages *service.MessageService
+	hub      *websocket.Hub
+	jwt      *jwtpkg.Manager
+	server   *Server
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := memory.NewStore(time.Hour)
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.example"}}}
+	messages := service.NewMessageService(store)
+	manager := jwtpkg.NewManager("test-secret-test-secret-test-secret", "tempmail", 15*time.Minute, time.Hour)
+
+	hub := websocket.NewHub(nil, nil, nil)
+	hub.SetReplayWindow(time.Minute)
+	go hub.Run(t.Context())
+
+	server := NewServer(service.NewMailboxService(store, store, cfg), messages, nil)
+	server.SetUserAccess(manager, service.NewAuthorizer(store))
+	server.SetEventHub(hub)
+
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return &testEnv{client: tempmailpb.NewTempMailClient(conn), store: store, messages: messages, hub: hub, jwt: manager, server: server}
+}
+
+// withToken 以 authorization: Bearer 携带凭证
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestServer(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := t.Context()
+
+	mailbox, err := env.client.CreateMailbox(ctx, &tempmailpb.CreateMailboxRequest{
+		Prefix: "grpc", Domain: "temp.example", ExpiresIn: durationpb.New(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "grpc@temp.example", mailbox.GetAddress())
+	assert.NotEmpty(t, mailbox.GetToken())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), mailbox.GetExpiresAt().AsTime(), time.Minute)
+
+	message, err := env.messages.Create(ctx, service.CreateMessageInput{
+		MailboxID: mailbox.GetId(), From: "a@example.com", To: mailbox.GetAddress(), Subject: "code",
+		Text: "Your verification code is 482913", Received: time.Now(),
+	})
+	require.NoError(t, err)
+
+	t.Run("创建邮箱参数错误", func(t *testing.T) {
+		_, err := env.client.CreateMailbox(ctx, &tempmailpb.CreateMailboxRequest{Domain: "other.example"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = env.client.CreateMailbox(ctx, &tempmailpb.CreateMailboxRequest{Prefix: "grpc", Domain: "temp.example"})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		_, err = env.client.CreateMailbox(ctx, &tempmailpb.CreateMailboxRequest{AutoRenew: true})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), "游客不能开启自动续期")
+	})
+
+	t.Run("访问邮箱需要令牌", func(t *testing.T) {
+		_, err := env.client.ListMessages(ctx, &tempmailpb.ListMessagesRequest{MailboxId: mailbox.GetId()})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		_, err = env.client.ListMessages(withToken(ctx, "wrong"), &tempmailpb.ListMessagesRequest{MailboxId: mailbox.GetId()})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		_, err = env.client.ListMessages(withToken(ctx, mailbox.GetToken()), &tempmailpb.ListMessagesRequest{MailboxId: "missing"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		stream, err := env.client.StreamMessages(ctx, &tempmailpb.StreamMessagesRequest{MailboxId: mailbox.GetId()})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("列出和读取邮件", func(t *testing.T) {
+		authed := metadata.AppendToOutgoingContext(ctx, "x-mailbox-token", mailbox.GetToken())
+		list, err := env.client.ListMessages(authed, &tempmailpb.ListMessagesRequest{MailboxId: mailbox.GetId()})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), list.GetTotal())
+		require.Len(t, list.GetMessages(), 1)
+		assert.Equal(t, message.ID, list.GetMessages()[0].GetId())
+		assert.Equal(t, "code", list.GetMessages()[0].GetSubject())
+
+		_, err = env.client.ListMessages(authed, &tempmailpb.ListMessagesRequest{MailboxId: mailbox.GetId(), Limit: -1})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		got, err := env.client.GetMessage(authed, &tempmailpb.GetMessageRequest{MailboxId: mailbox.GetId(), MessageId: message.ID})
+		require.NoError(t, err)
+		assert.Equal(t, "Your verification code is 482913", got.GetText())
+		assert.Equal(t, "482913", got.GetVerification().GetCode())
+
+		_, err = env.client.GetMessage(authed, &tempmailpb.GetMessageRequest{MailboxId: mailbox.GetId(), MessageId: "missing"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("所有者使用访问令牌", func(t *testing.T) {
+		tokens, err := env.jwt.GenerateTokenPair(ctx, "user-1", "user@example.com", "free")
+		require.NoError(t, err)
+		authed := withToken(ctx, tokens.AccessToken)
+
+		owned, err := env.client.CreateMailbox(authed, &tempmailpb.CreateMailboxRequest{Domain: "temp.example", AutoRenew: true})
+		require.NoError(t, err)
+		assert.True(t, owned.GetAutoRenew())
+
+		_, err = env.client.ListMessages(authed, &tempmailpb.ListMessagesRequest{MailboxId: owned.GetId()})
+		assert.NoError(t, err)
+		_, err = env.client.ListMessages(authed, &tempmailpb.ListMessagesRequest{MailboxId: mailbox.GetId()})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "不能访问其他人的邮箱")
+	})
+
+	t.Run("推送新邮件", func(t *testing.T) {
+		streamCtx, cancel := context.WithCancel(withToken(ctx, mailbox.GetToken()))
+		defer cancel()
+		stream, err := env.client.StreamMessages(streamCtx, &tempmailpb.StreamMessagesRequest{MailboxId: mailbox.GetId()})
+		require.NoError(t, err)
+
+		// 订阅后推送的邮件
+		require.Eventually(t, func() bool { return env.hub.ConnectionCount() == 1 }, time.Second, 5*time.Millisecond)
+		env.hub.NotifyNewMail(streamCtx, mailbox.GetId(), &domain.Message{
+			ID: "m-2", MailboxID: mailbox.GetId(), Seq: 2, Subject: "live", Text: "hello", CreatedAt: time.Now(),
+		})
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.False(t, event.GetReplayed())
+		assert.Equal(t, "m-2", event.GetMessage().GetId())
+		assert.Equal(t, int64(2), event.GetMessage().GetSeq())
+		assert.Equal(t, "hello", event.GetMessage().GetPreview())
+
+		// 重连时按 since_seq 补发
+		cancel()
+		require.Eventually(t, func() bool { return env.hub.ConnectionCount() == 0 }, time.Second, 5*time.Millisecond)
+		resumed, err := env.client.StreamMessages(withToken(ctx, mailbox.GetToken()), &tempmailpb.StreamMessagesRequest{MailboxId: mailbox.GetId(), SinceSeq: 1})
+		require.NoError(t, err)
+		event, err = resumed.Recv()
+		require.NoError(t, err)
+		assert.True(t, event.GetReplayed())
+		assert.Equal(t, "m-2", event.GetMessage().GetId())
+
+		// 邮箱删除后结束流
+		env.hub.NotifyMailboxDeleted(mailbox.GetId())
+		_, err = resumed.Recv()
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+// maintenanceSwitch 可切换的维护模式状态
+type maintenanceSwitch struct {
+	readOnly atomic.Bool
+}
+
+func (m *maintenanceSwitch) MaintenanceState() (bool, string) {
+	return m.readOnly.Load(), "database upgrade"
+}
+
+// fakeQuota 每个用户固定次数的请求配额
+type fakeQuota struct {
+	limit int
+	mu    sync.Mutex
+	used  map[string]int
+	tiers []domain.UserTier
+}
+
+func (q *fakeQuota) Take(_ context.Context, userID string, tier domain.UserTier) (*middleware.QuotaWindow, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.used[userID]++
+	q.tiers = append(q.tiers, tier)
+	window := &middleware.QuotaWindow{Limit: q.limit, Remaining: q.limit - q.used[userID], Reset: time.Now().Add(30 * time.Second)}
+	return window, q.used[userID] <= q.limit
+}
+
+func TestServer_Limits(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := t.Context()
+	maintenance := &maintenanceSwitch{}
+	quota := &fakeQuota{limit: 2, used: make(map[string]int)}
+	env.server.SetMaintenanceChecker(maintenance)
+	env.server.SetRequestQuota(quota)
+
+	mailbox, err := env.client.CreateMailbox(ctx, &tempmailpb.CreateMailboxRequest{Domain: "temp.example"})
+	require.NoError(t, err)
+
+	t.Run("维护只读模式下拒绝创建邮箱，读取不受影响", func(t *testing.T) {
+		maintenance.readOnly.Store(true)
+		defer maintenance.readOnly.Store(false)
+
+		_, err := env.client.CreateMailbox(ctx, &tempmailpb.CreateMailboxRequest{Domain: "temp.example"})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, "database upgrade", status.Convert(err).Message())
+
+		_, err = env.client.ListMessages(withToken(ctx, mailbox.GetToken()), &tempmailpb.ListMessagesRequest{MailboxId: mailbox.GetId()})
+		assert.NoError(t, err)
+	})
+
+	t.Run("访问令牌调用计入请求配额，邮箱令牌不计入", func(t *testing.T) {
+		tokens, err := env.jwt.GenerateTokenPair(ctx, "user-1", "user@example.com", string(domain.TierPro))
+		require.NoError(t, err)
+		authed := withToken(ctx, tokens.AccessToken)
+
+		var header metadata.MD
+		owned, err := env.client.CreateMailbox(authed, &tempmailpb.CreateMailboxRequest{Domain: "temp.example"}, grpc.Header(&header))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1"}, header.Get("x-ratelimit-remaining"))
+		_, err = env.client.ListMessages(withToken(ctx, mailbox.GetToken()), &tempmailpb.ListMessagesRequest{MailboxId: mailbox.GetId()})
+		require.NoError(t, err)
+		_, err = env.client.CreateMailbox(authed, &tempmailpb.CreateMailboxRequest{Domain: "temp.example"})
+		require.NoError(t, err)
+
+		_, err = env.client.CreateMailbox(authed, &tempmailpb.CreateMailboxRequest{Domain: "temp.example"}, grpc.Header(&header))
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, []string{"0"}, header.Get("x-ratelimit-remaining"))
+		assert.NotEmpty(t, header.Get("retry-after"))
+
+		stream, err := env.client.StreamMessages(authed, &tempmailpb.StreamMessagesRequest{MailboxId: owned.GetId()})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err), "流式调用同样计入")
+
+		assert.Equal(t, 4, quota.used["user-1"])
+		assert.Len(t, quota.used, 1)
+		assert.Equal(t, domain.TierPro, quota.tiers[0])
+	})
+}
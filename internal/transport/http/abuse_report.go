@@ -0,0 +1,258 @@
+package httptransport
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage"
+)
+
+// AbuseReportHandler 滥用举报处理器：公开提交，管理员分拣和处理
+type AbuseReportHandler struct {
+	reports *service.AbuseReportService
+}
+
+// NewAbuseReportHandler 创建滥用举报处理器
+func NewAbuseReportHandler(reports *service.AbuseReportService) *AbuseReportHandler {
+	return &AbuseReportHandler{reports: reports}
+}
+
+// SubmitAbuseReportRequest 提交举报请求（只接受 JSON，不支持附件）
+type SubmitAbuseReportRequest struct {
+	ReporterEmail string `json:"reporterEmail"` // 可选，便于管理员联系举报人
+	Target        string `json:"target" binding:"required"`
+	Category      string `json:"category" binding:"required"`
+	Details       string `json:"details" binding:"required"`
+}
+
+// submitAbuseReportResponse 举报人只能看到举报编号和状态，不返回关联的邮箱或邮件
+type submitAbuseReportResponse struct {
+	ID        string                   `json:"id"`
+	Status    domain.AbuseReportStatus `json:"status"`
+	CreatedAt time.Time                `json:"createdAt"`
+}
+
+// TriageAbuseReportRequest 分拣举报请求
+type TriageAbuseReportRequest struct {
+	Note string `json:"note"`
+}
+
+// ResolveAbuseReportRequest 处理举报请求
+type ResolveAbuseReportRequest struct {
+	Action string `json:"action" binding:"required"` // quarantine、suspend 或 dismiss
+	Note   string `json:"note"`
+}
+
+// SetMailboxSuspendedRequest 停用或恢复邮箱请求
+type SetMailboxSuspendedRequest struct {
+	Suspended *bool `json:"suspended" binding:"required"`
+}
+
+// Submit godoc
+// @Summary 提交滥用举报
+// @Description 举报经由本实例投递的滥用邮件（公开接口，按 IP 严格限流）。target 为邮箱地址或邮件链接，category 为 spam、phishing、illegal 或 other
+// @Tags Public
+// @Accept json
+// @Produce json
+// @Param request body SubmitAbuseReportRequest true "举报内容"
+// @Success 200 {object} Response{data=submitAbuseReportResponse}
+// @Failure 400 {object} Response
+// @Failure 413 {object} Response
+// @Failure 429 {object} Response
+// @Router /v1/public/abuse-reports [post]
+func (h *AbuseReportHandler) Submit(c *gin.Context) {
+	var req SubmitAbuseReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	report, err := h.reports.Submit(c.Request.Context(), service.SubmitAbuseReportInput{
+		ReporterEmail: req.ReporterEmail,
+		ReporterIP:    c.ClientIP(),
+		Target:        req.Target,
+		Category:      domain.AbuseCategory(req.Category),
+		Details:       req.Details,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAbuseTargetInvalid),
+			errors.Is(err, service.ErrAbuseCategoryInvalid),
+			errors.Is(err, service.ErrAbuseDetailsInvalid),
+			errors.Is(err, service.ErrAbuseReporterInvalid):
+			BadRequest(c, GetErrorMessage(err))
+		default:
+			InternalError(c, MsgAbuseReportSubmitFailed)
+		}
+		return
+	}
+
+	Success(c, submitAbuseReportResponse{ID: report.ID, Status: report.Status, CreatedAt: report.CreatedAt})
+}
+
+// List godoc
+// @Summary 滥用举报列表
+// @Description 按状态、类别、邮箱过滤举报（新举报在前），priority=true 只列出被多人举报、需优先处理的举报
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "new、triaged、actioned 或 dismissed"
+// @Param category query string false "spam、phishing、illegal 或 other"
+// @Param mailboxId query string false "邮箱ID"
+// @Param priority query boolean false "只列出优先处理的举报"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量（最大100）" default(20)
+// @Success 200 {object} Response{data=service.ListAbuseReportsOutput}
+// @Router /v1/admin/abuse-reports [get]
+func (h *AbuseReportHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	result, err := h.reports.List(c.Request.Context(), service.ListAbuseReportsInput{
+		Filter: domain.AbuseReportFilter{
+			Status:    domain.AbuseReportStatus(c.Query("status")),
+			Category:  domain.AbuseCategory(c.Query("category")),
+			MailboxID: c.Query("mailboxId"),
+			Priority:  c.Query("priority") == "true",
+		},
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		InternalError(c, MsgAbuseReportListFailed)
+		return
+	}
+
+	Success(c, result)
+}
+
+// Get godoc
+// @Summary 滥用举报详情
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "举报ID"
+// @Success 200 {object} Response{data=domain.AbuseReport}
+// @Failure 404 {object} Response
+// @Router /v1/admin/abuse-reports/{id} [get]
+func (h *AbuseReportHandler) Get(c *gin.Context) {
+	report, err := h.reports.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	Success(c, report)
+}
+
+// Triage godoc
+// @Summary 分拣滥用举报
+// @Description 确认举报待处理（new -> triaged，记录审计日志）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "举报ID"
+// @Param request body TriageAbuseReportRequest false "处理备注"
+// @Success 200 {object} Response{data=domain.AbuseReport}
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/admin/abuse-reports/{id}/triage [post]
+func (h *AbuseReportHandler) Triage(c *gin.Context) {
+	var req TriageAbuseReportRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+	}
+
+	report, err := h.reports.Triage(c.Request.Context(), c.Param("id"), c.GetString("userID"), req.Note)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	Success(c, report)
+}
+
+// Resolve godoc
+// @Summary 处理滥用举报
+// @Description 隔离被举报的邮件（quarantine）、停用邮箱（suspend）或驳回（dismiss），记录审计日志
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "举报ID"
+// @Param request body ResolveAbuseReportRequest true "处置方式"
+// @Success 200 {object} Response{data=domain.AbuseReport}
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/admin/abuse-reports/{id}/resolve [post]
+func (h *AbuseReportHandler) Resolve(c *gin.Context) {
+	var req ResolveAbuseReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	report, err := h.reports.Resolve(c.Request.Context(), c.Param("id"), c.GetString("userID"), service.ResolveAbuseReportInput{
+		Action: domain.AbuseAction(req.Action),
+		Note:   req.Note,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	Success(c, report)
+}
+
+// SetMailboxSuspended godoc
+// @Summary 停用或恢复邮箱
+// @Description 停用后邮箱的读取接口返回 403、SMTP 投递返回 550，已建立的实时订阅被撤销；邮件保留（记录审计日志）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "邮箱ID"
+// @Param request body SetMailboxSuspendedRequest true "是否停用"
+// @Success 200 {object} Response{data=adminMailboxResponse}
+// @Failure 404 {object} Response
+// @Router /v1/admin/mailboxes/{id}/suspended [patch]
+func (h *AbuseReportHandler) SetMailboxSuspended(c *gin.Context) {
+	var req SetMailboxSuspendedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	mb, err := h.reports.SetMailboxSuspended(c.Request.Context(), c.Param("id"), c.GetString("userID"), *req.Suspended)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	Success(c, newAdminMailboxResponse(*mb))
+}
+
+func (h *AbuseReportHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAbuseReportNotFound):
+		NotFound(c, GetErrorMessage(err))
+	case errors.Is(err, storage.ErrMailboxNotFound):
+		NotFound(c, MsgMailboxNotFound)
+	case errors.Is(err, service.ErrAbuseReportTransition), errors.Is(err, service.ErrAbuseReportUnlinked):
+		Conflict(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrAbuseActionInvalid):
+		BadRequest(c, GetErrorMessage(err))
+	default:
+		InternalError(c, MsgAbuseReportUpdateFailed)
+	}
+}
@@ -0,0 +1,109 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestAbuseReportHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "scam@temp.mail", LocalPart: "scam", Domain: "temp.mail", Token: "secret-token",
+		CreatedAt: time.Now(),
+	}))
+	mailboxes := service.NewMailboxService(store, store, &config.Config{})
+	h := NewAbuseReportHandler(service.NewAbuseReportService(store, mailboxes))
+	mailboxAuth := middleware.NewMailboxAuth(mailboxes)
+
+	router := gin.New()
+	router.POST("/v1/public/abuse-reports", middleware.IPRateLimit(5, time.Hour), middleware.BodySizeLimit(16*1024), h.Submit)
+	admin := router.Group("/v1/admin", func(c *gin.Context) { c.Set("userID", "admin-1") })
+	admin.GET("/abuse-reports", h.List)
+	admin.GET("/abuse-reports/:id", h.Get)
+	admin.POST("/abuse-reports/:id/triage", h.Triage)
+	admin.POST("/abuse-reports/:id/resolve", h.Resolve)
+	admin.PATCH("/mailboxes/:id/suspended", h.SetMailboxSuspended)
+	router.GET("/v1/mailboxes/:id/messages", mailboxAuth.RequireMailboxToken(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Mailbox-Token", "secret-token")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var reportID string
+	t.Run("提交举报", func(t *testing.T) {
+		w := do(http.MethodPost, "/v1/public/abuse-reports", `{"target":"scam@temp.mail","category":"malware","details":"x"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do(http.MethodPost, "/v1/public/abuse-reports", `{"target":"scam@temp.mail","category":"phishing","details":"fake bank login"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "mb-1", "不向举报人返回关联的邮箱")
+		var resp struct {
+			Data submitAbuseReportResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, domain.AbuseReportStatusNew, resp.Data.Status)
+		reportID = resp.Data.ID
+	})
+
+	t.Run("按 IP 限流", func(t *testing.T) {
+		for range 3 {
+			do(http.MethodPost, "/v1/public/abuse-reports", `{"target":"scam@temp.mail","category":"spam","details":"spam"}`)
+		}
+		w := do(http.MethodPost, "/v1/public/abuse-reports", `{"target":"scam@temp.mail","category":"spam","details":"spam"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+
+	t.Run("管理员分拣并停用邮箱", func(t *testing.T) {
+		w := do(http.MethodGet, "/v1/admin/abuse-reports?status=new&category=phishing", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var list struct {
+			Data service.ListAbuseReportsOutput `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		require.Len(t, list.Data.Reports, 1)
+		assert.Equal(t, "mb-1", list.Data.Reports[0].MailboxID)
+
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "/v1/admin/abuse-reports/"+reportID+"/triage", "").Code)
+		assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/v1/admin/abuse-reports/"+reportID+"/triage", "").Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/v1/admin/abuse-reports/"+reportID+"/resolve", `{"action":"delete"}`).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/v1/admin/abuse-reports/missing", "").Code)
+
+		w = do(http.MethodPost, "/v1/admin/abuse-reports/"+reportID+"/resolve", `{"action":"suspend","note":"phishing kit"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"actioned"`)
+
+		w = do(http.MethodGet, "/v1/mailboxes/mb-1/messages", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "mailbox suspended")
+	})
+
+	t.Run("恢复邮箱", func(t *testing.T) {
+		w := do(http.MethodPatch, "/v1/admin/mailboxes/mb-1/suspended", `{"suspended":false}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusNoContent, do(http.MethodGet, "/v1/mailboxes/mb-1/messages", "").Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPatch, "/v1/admin/mailboxes/missing/suspended", `{"suspended":true}`).Code)
+	})
+}
@@ -0,0 +1,237 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/auth"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// AccountHandler 用户自助账户管理处理器
+type AccountHandler struct {
+	accounts *service.AccountService
+	log      *zap.Logger
+}
+
+// NewAccountHandler 创建账户管理处理器
+func NewAccountHandler(accounts *service.AccountService, log *zap.Logger) *AccountHandler {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	return &AccountHandler{accounts: accounts, log: log}
+}
+
+type updateProfileRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
+
+type verifyEmailRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type deleteAccountRequest struct {
+	Password string `json:"password"` // 有密码的账户必填
+	Confirm  string `json:"confirm"`  // 没有密码（仅第三方登录）的账户填写账户邮箱
+}
+
+// newUserResponse 用户信息响应
+func newUserResponse(user *domain.User) userResponse {
+	return userResponse{
+		ID:               user.ID,
+		Email:            user.Email,
+		Username:         user.Username,
+		Tier:             string(user.Tier),
+		IsActive:         user.IsActive,
+		IsEmailVerified:  user.IsEmailVerified,
+		TwoFactorEnabled: user.TOTPEnabled,
+	}
+}
+
+// respondAccountError 账户管理接口的通用错误映射
+func (h *AccountHandler) respondAccountError(c *gin.Context, err error) {
+	if respondLoginThrottled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		NotFound(c, MsgUserNotFound)
+	case errors.Is(err, auth.ErrInvalidUsername), errors.Is(err, auth.ErrInvalidPassword):
+		BadRequest(c, err.Error())
+	case errors.Is(err, auth.ErrIncorrectPassword):
+		Error(c, http.StatusForbidden, MsgIncorrectPassword)
+	case errors.Is(err, auth.ErrUsernameExists):
+		Conflict(c, MsgUsernameExists)
+	case errors.Is(err, service.ErrEmailVerificationUnavailable):
+		Error(c, http.StatusServiceUnavailable, GetErrorMessage(err))
+	case errors.Is(err, service.ErrEmailAlreadyVerified):
+		Conflict(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrEmailCodeCooldown):
+		Error(c, http.StatusTooManyRequests, GetErrorMessage(err))
+	case errors.Is(err, service.ErrEmailCodeInvalid), errors.Is(err, service.ErrEmailCodeExpired),
+		errors.Is(err, service.ErrAccountConfirmMismatch):
+		BadRequest(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrAccountIsSuper), errors.Is(err, service.ErrAccountOwnsOrganization):
+		Forbidden(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrSendRelayFailed):
+		Error(c, http.StatusBadGateway, GetErrorMessage(service.ErrSendRelayFailed))
+	default:
+		h.log.Error("account operation failed", zap.String("user_id", c.GetString("userID")), zap.Error(err))
+		InternalError(c, MsgAccountUpdateFailed)
+	}
+}
+
+// GetProfile godoc
+// @Summary 获取个人资料
+// @Tags 用户
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=userResponse}
+// @Failure 401 {object} Response "未认证"
+// @Router /v1/user/profile [get]
+func (h *AccountHandler) GetProfile(c *gin.Context) {
+	user, err := h.accounts.Get(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		h.respondAccountError(c, err)
+		return
+	}
+	Success(c, newUserResponse(user))
+}
+
+// UpdateProfile godoc
+// @Summary 修改用户名
+// @Description 用户名 3-32 位字母、数字、点、下划线或连字符，不区分大小写唯一
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body updateProfileRequest true "新用户名"
+// @Success 200 {object} Response{data=userResponse}
+// @Failure 400 {object} Response "用户名格式无效"
+// @Failure 409 {object} Response "用户名已被占用"
+// @Router /v1/user/profile [patch]
+func (h *AccountHandler) UpdateProfile(c *gin.Context) {
+	var req updateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	user, err := h.accounts.ChangeUsername(c.Request.Context(), c.GetString("userID"), req.Username)
+	if err != nil {
+		h.respondAccountError(c, err)
+		return
+	}
+	Success(c, newUserResponse(user))
+}
+
+// ChangePassword godoc
+// @Summary 修改密码
+// @Description 校验当前密码后修改密码，其他设备上的登录会话随之注销。当前密码错误计入登录失败次数
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body changePasswordRequest true "当前密码和新密码"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response "新密码不符合要求"
+// @Failure 403 {object} Response "当前密码错误"
+// @Failure 423 {object} Response "账户已临时锁定"
+// @Router /v1/user/profile/password [put]
+func (h *AccountHandler) ChangePassword(c *gin.Context) {
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	var sessionID string
+	if claims := tokenClaims(c); claims != nil {
+		sessionID = claims.SessionID
+	}
+	userID := c.GetString("userID")
+	if err := h.accounts.ChangePassword(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword, sessionID, c.ClientIP()); err != nil {
+		h.respondAccountError(c, err)
+		return
+	}
+	h.log.Info("password changed", zap.String("user_id", userID))
+	SuccessWithMsg(c, "密码已修改", nil)
+}
+
+// SendEmailVerification godoc
+// @Summary 发送邮箱验证码
+// @Description 向账户邮箱发送 6 位验证码（30 分钟内有效，每分钟最多发送一次），重新发送后旧验证码失效
+// @Tags 用户
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response
+// @Failure 409 {object} Response "邮箱已验证"
+// @Failure 429 {object} Response "发送过于频繁"
+// @Failure 503 {object} Response "未配置发信中继"
+// @Router /v1/user/profile/email/verification [post]
+func (h *AccountHandler) SendEmailVerification(c *gin.Context) {
+	if err := h.accounts.SendEmailVerification(c.Request.Context(), c.GetString("userID")); err != nil {
+		h.respondAccountError(c, err)
+		return
+	}
+	SuccessWithMsg(c, "验证码已发送", nil)
+}
+
+// VerifyEmail godoc
+// @Summary 验证邮箱
+// @Description 提交邮件中的验证码完成邮箱验证，每个验证码最多尝试 5 次
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body verifyEmailRequest true "验证码"
+// @Success 200 {object} Response{data=userResponse}
+// @Failure 400 {object} Response "验证码错误或已过期"
+// @Router /v1/user/profile/email/verify [post]
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	var req verifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	user, err := h.accounts.VerifyEmail(c.Request.Context(), c.GetString("userID"), req.Code)
+	if err != nil {
+		h.respondAccountError(c, err)
+		return
+	}
+	Success(c, newUserResponse(user))
+}
+
+// DeleteAccount godoc
+// @Summary 注销账户
+// @Description 永久删除账户及其个人邮箱（含邮件）、个人域名和 API Key，并注销全部登录会话。有密码的账户须提供当前密码，仅通过第三方登录的账户须在 confirm 中填写账户邮箱。组织所有者须先转让或删除组织
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body deleteAccountRequest true "确认信息"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response "确认信息不匹配"
+// @Failure 403 {object} Response "密码错误、超级管理员或组织所有者"
+// @Router /v1/user/profile [delete]
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	var req deleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	userID := c.GetString("userID")
+	if err := h.accounts.DeleteAccount(c.Request.Context(), userID, req.Password, req.Confirm, c.ClientIP()); err != nil {
+		h.respondAccountError(c, err)
+		return
+	}
+	h.log.Info("account deleted", zap.String("user_id", userID))
+	SuccessWithMsg(c, "账户已注销", nil)
+}
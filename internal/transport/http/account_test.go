@@ -0,0 +1,109 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/auth"
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestAccountHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(time.Hour)
+	authService := auth.NewService(store)
+	jwtManager := jwtpkg.NewManager("test-secret-0123456789abcdefghijklmnop", "test", time.Hour, 24*time.Hour)
+	sessions := auth.NewSessionService(store, store, jwtManager)
+	accounts := service.NewAccountService(store, authService, store, service.AccountOptions{Domain: "temp.example"})
+	accounts.SetSessions(sessions)
+
+	authHandler := NewAuthHandler(authService, jwtManager)
+	authHandler.SetSessions(sessions)
+	h := NewAccountHandler(accounts, nil)
+	jwtAuth := middleware.NewJWTAuth(jwtManager)
+	jwtManager.SetBlacklist(sessions)
+
+	router := gin.New()
+	router.POST("/v1/auth/register", authHandler.Register)
+	router.POST("/v1/auth/login", authHandler.Login)
+	profile := router.Group("/v1/user/profile", jwtAuth.RequireAuth())
+	profile.GET("", h.GetProfile)
+	profile.PATCH("", h.UpdateProfile)
+	profile.PUT("/password", h.ChangePassword)
+	profile.POST("/email/verification", h.SendEmailVerification)
+	profile.DELETE("", h.DeleteAccount)
+
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(t *testing.T, password string) authResponse {
+		w := serve(http.MethodPost, "/v1/auth/login", "", `{"username":"alice@example.com","password":"`+password+`"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp authResponse
+		require.NoError(t, json.Unmarshal(mustData(t, w), &resp))
+		return resp
+	}
+
+	w := serve(http.MethodPost, "/v1/auth/register", "", `{"email":"alice@example.com","password":"password123","username":"alice"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	t.Run("修改用户名", func(t *testing.T) {
+		tokens := login(t, "password123")
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPatch, "/v1/user/profile", tokens.AccessToken, `{"username":"a"}`).Code)
+
+		w := serve(http.MethodPatch, "/v1/user/profile", tokens.AccessToken, `{"username":"alice2"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var user userResponse
+		require.NoError(t, json.Unmarshal(mustData(t, serve(http.MethodGet, "/v1/user/profile", tokens.AccessToken, "")), &user))
+		assert.Equal(t, "alice2", user.Username)
+	})
+
+	t.Run("修改密码后其他设备的会话失效", func(t *testing.T) {
+		laptop := login(t, "password123")
+		phone := login(t, "password123")
+
+		w := serve(http.MethodPut, "/v1/user/profile/password", laptop.AccessToken, `{"currentPassword":"wrong-password","newPassword":"newpassword456"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = serve(http.MethodPut, "/v1/user/profile/password", laptop.AccessToken, `{"currentPassword":"password123","newPassword":"newpassword456"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/user/profile", laptop.AccessToken, "").Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/v1/user/profile", phone.AccessToken, "").Code)
+		login(t, "newpassword456")
+	})
+
+	t.Run("未配置发信中继时返回 503", func(t *testing.T) {
+		tokens := login(t, "newpassword456")
+		assert.Equal(t, http.StatusServiceUnavailable, serve(http.MethodPost, "/v1/user/profile/email/verification", tokens.AccessToken, "").Code)
+	})
+
+	t.Run("注销账户后令牌失效且不能再登录", func(t *testing.T) {
+		tokens := login(t, "newpassword456")
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, "/v1/user/profile", tokens.AccessToken, `{"password":"password123"}`).Code)
+
+		w := serve(http.MethodDelete, "/v1/user/profile", tokens.AccessToken, `{"password":"newpassword456"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/v1/user/profile", tokens.AccessToken, "").Code)
+		w = serve(http.MethodPost, "/v1/auth/login", "", `{"username":"alice@example.com","password":"newpassword456"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -1,12 +1,16 @@
 package httptransport
 
 import (
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
 	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage"
 )
 
 // AdminHandler 管理API处理器
@@ -74,7 +78,7 @@ func (h *AdminHandler) ListUsers(c *gin.Context) {
 		IsActive: isActive,
 	}
 
-	result, err := h.adminService.ListUsers(input)
+	result, err := h.adminService.ListUsers(c.Request.Context(), input)
 	if err != nil {
 		InternalError(c, MsgUserListFailed)
 		return
@@ -95,7 +99,7 @@ func (h *AdminHandler) ListUsers(c *gin.Context) {
 func (h *AdminHandler) GetUser(c *gin.Context) {
 	userID := c.Param("id")
 
-	user, err := h.adminService.GetUser(userID)
+	user, err := h.adminService.GetUser(c.Request.Context(), userID)
 	if err != nil {
 		if err == service.ErrAdminUserNotFound {
 			NotFound(c, MsgUserNotFound)
@@ -139,6 +143,11 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	var before map[string]any
+	if existing, err := h.adminService.GetUser(c.Request.Context(), userID); err == nil {
+		before = service.AuditSnapshot(existing)
+	}
+
 	input := service.UpdateUserInput{
 		UserID:          userID,
 		Role:            req.Role,
@@ -148,7 +157,7 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 		OperatorID:      operatorID,
 	}
 
-	user, err := h.adminService.UpdateUser(input)
+	user, err := h.adminService.UpdateUser(c.Request.Context(), input)
 	if err != nil {
 		switch err {
 		case service.ErrAdminUserNotFound:
@@ -165,6 +174,8 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	audit := middleware.AuditEntry(c)
+	audit.Action, audit.TargetType, audit.Changes = service.AuditUserUpdate, domain.AuditTargetUser, service.AuditDiff(before, user)
 	Success(c, user)
 }
 
@@ -181,7 +192,12 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 	userID := c.Param("id")
 	operatorID := c.GetString("userID")
 
-	err := h.adminService.DeleteUser(userID, operatorID)
+	var before map[string]any
+	if existing, err := h.adminService.GetUser(c.Request.Context(), userID); err == nil {
+		before = service.AuditSnapshot(existing)
+	}
+
+	err := h.adminService.DeleteUser(c.Request.Context(), userID, operatorID)
 	if err != nil {
 		switch err {
 		case service.ErrAdminUserNotFound:
@@ -196,9 +212,150 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	audit := middleware.AuditEntry(c)
+	audit.Action, audit.TargetType, audit.Changes = service.AuditUserDelete, domain.AuditTargetUser, service.AuditDiff(before, nil)
+	NoContent(c)
+}
+
+// ForceDeleteMailbox godoc
+// @Summary 强制删除邮箱
+// @Description 管理员删除任意邮箱及其邮件、别名、标签、文件内容和缓存，并通知在线客户端
+// @Tags Admin
+// @Param id path string true "邮箱ID"
+// @Success 204
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /v1/admin/mailboxes/{id} [delete]
+func (h *AdminHandler) ForceDeleteMailbox(c *gin.Context) {
+	if err := h.adminService.ForceDeleteMailbox(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, storage.ErrMailboxNotFound) {
+			NotFound(c, MsgMailboxNotFound)
+			return
+		}
+		InternalError(c, MsgMailboxDeleteFailed)
+		return
+	}
+
 	NoContent(c)
 }
 
+// adminMailboxResponse 管理员邮箱列表项（不含访问令牌）
+type adminMailboxResponse struct {
+	ID             string     `json:"id"`
+	Address        string     `json:"address"`
+	UserID         *string    `json:"userId,omitempty"`
+	OrgID          *string    `json:"orgId,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	Total          int        `json:"total"`
+	LastAccessedAt *time.Time `json:"lastAccessedAt,omitempty"`
+	Idle           bool       `json:"idle"`
+	IdleSince      *time.Time `json:"idleSince,omitempty"`
+	IsPublic       bool       `json:"isPublic"`
+	Suspended      bool       `json:"suspended"`
+}
+
+func newAdminMailboxResponse(mb domain.Mailbox) adminMailboxResponse {
+	return adminMailboxResponse{
+		ID:             mb.ID,
+		Address:        mb.Address,
+		UserID:         mb.UserID,
+		OrgID:          mb.OrgID,
+		CreatedAt:      mb.CreatedAt,
+		ExpiresAt:      mb.ExpiresAt,
+		Total:          mb.TotalCount,
+		LastAccessedAt: mb.LastAccessedAt,
+		Idle:           mb.IdleSince != nil,
+		IdleSince:      mb.IdleSince,
+		IsPublic:       mb.IsPublic,
+		Suspended:      mb.Suspended,
+	}
+}
+
+type adminMailboxListResponse struct {
+	Items      []adminMailboxResponse `json:"items"`
+	Total      int                    `json:"total"`
+	Page       int                    `json:"page"`
+	PageSize   int                    `json:"pageSize"`
+	TotalPages int                    `json:"totalPages"`
+}
+
+// ListMailboxes godoc
+// @Summary 获取邮箱列表
+// @Description 列出所有邮箱及最近访问时间、闲置状态，按最近访问时间从早到晚排序（需要管理员权限）
+// @Tags Admin
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(20)
+// @Param idle query bool false "只列出闲置邮箱"
+// @Success 200 {object} adminMailboxListResponse
+// @Failure 403 {object} Response
+// @Router /v1/admin/mailboxes [get]
+func (h *AdminHandler) ListMailboxes(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	result, err := h.adminService.ListMailboxes(c.Request.Context(), service.ListMailboxesInput{
+		Page:     page,
+		PageSize: pageSize,
+		IdleOnly: c.Query("idle") == "true",
+	})
+	if err != nil {
+		InternalError(c, MsgInternalError)
+		return
+	}
+
+	items := make([]adminMailboxResponse, 0, len(result.Mailboxes))
+	for _, mb := range result.Mailboxes {
+		items = append(items, newAdminMailboxResponse(mb))
+	}
+
+	Success(c, adminMailboxListResponse{
+		Items:      items,
+		Total:      result.Total,
+		Page:       result.Page,
+		PageSize:   result.PageSize,
+		TotalPages: result.TotalPages,
+	})
+}
+
+// SetMailboxPublicRequest 设置公开收件箱请求
+type SetMailboxPublicRequest struct {
+	IsPublic *bool `json:"isPublic" binding:"required"`
+}
+
+// SetMailboxPublic godoc
+// @Summary 设置公开收件箱
+// @Description 将邮箱设为公开收件箱（任何人无需令牌只读查看，邮件 1 小时后自动删除）或取消公开；取消后立即撤销匿名 WebSocket 订阅（需要管理员权限）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param request body SetMailboxPublicRequest true "公开状态"
+// @Success 200 {object} adminMailboxResponse
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/admin/mailboxes/{id}/public [patch]
+func (h *AdminHandler) SetMailboxPublic(c *gin.Context) {
+	var req SetMailboxPublicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	mb, err := h.adminService.SetMailboxPublic(c.Request.Context(), c.Param("id"), *req.IsPublic)
+	if err != nil {
+		if errors.Is(err, storage.ErrMailboxNotFound) {
+			NotFound(c, MsgMailboxNotFound)
+			return
+		}
+		InternalError(c, MsgPublicInboxUpdateFailed)
+		return
+	}
+
+	Success(c, newAdminMailboxResponse(*mb))
+}
+
 // ========== 系统域名管理 ==========
 
 // ListSystemDomains godoc
@@ -210,7 +367,7 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 // @Failure 500 {object} Response
 // @Router /v1/admin/domains [get]
 func (h *AdminHandler) ListSystemDomains(c *gin.Context) {
-	domains, err := h.systemDomainService.ListSystemDomains()
+	domains, err := h.systemDomainService.ListSystemDomains(c.Request.Context())
 	if err != nil {
 		InternalError(c, MsgDomainListFailedAdmin)
 		return
@@ -256,13 +413,15 @@ func (h *AdminHandler) AddSystemDomain(c *gin.Context) {
 		Notes:     req.Notes,
 	}
 
-	sysDomain, err := h.systemDomainService.AddSystemDomain(input)
+	sysDomain, err := h.systemDomainService.AddSystemDomain(c.Request.Context(), input)
 	if err != nil {
 		switch err {
 		case service.ErrInvalidSystemDomain:
 			BadRequest(c, "无效的域名格式")
 		case service.ErrSystemDomainAlreadyExists:
 			Conflict(c, MsgDomainAlreadyExists)
+		case service.ErrSystemDomainOwnedByUser:
+			Conflict(c, GetErrorMessage(err))
 		default:
 			InternalError(c, MsgDomainAddFailedAdmin)
 		}
@@ -284,7 +443,7 @@ func (h *AdminHandler) AddSystemDomain(c *gin.Context) {
 func (h *AdminHandler) GetSystemDomain(c *gin.Context) {
 	domainID := c.Param("id")
 
-	sysDomain, err := h.systemDomainService.GetSystemDomain(domainID)
+	sysDomain, err := h.systemDomainService.GetSystemDomain(c.Request.Context(), domainID)
 	if err != nil {
 		if err == service.ErrSystemDomainNotFound {
 			NotFound(c, MsgDomainNotFoundAdmin)
@@ -310,7 +469,7 @@ func (h *AdminHandler) GetSystemDomain(c *gin.Context) {
 func (h *AdminHandler) VerifySystemDomain(c *gin.Context) {
 	domainID := c.Param("id")
 
-	sysDomain, err := h.systemDomainService.VerifySystemDomain(domainID)
+	sysDomain, err := h.systemDomainService.VerifySystemDomain(c.Request.Context(), domainID)
 	if err != nil {
 		switch err {
 		case service.ErrSystemDomainNotFound:
@@ -338,7 +497,7 @@ func (h *AdminHandler) VerifySystemDomain(c *gin.Context) {
 func (h *AdminHandler) GetSystemDomainInstructions(c *gin.Context) {
 	domainID := c.Param("id")
 
-	instructions, err := h.systemDomainService.GetSetupInstructions(domainID)
+	instructions, err := h.systemDomainService.GetSetupInstructions(c.Request.Context(), domainID)
 	if err != nil {
 		if err == service.ErrSystemDomainNotFound {
 			NotFound(c, MsgDomainNotFoundAdmin)
@@ -377,7 +536,7 @@ func (h *AdminHandler) ToggleSystemDomainStatus(c *gin.Context) {
 		return
 	}
 
-	sysDomain, err := h.systemDomainService.ToggleDomainStatus(domainID, req.IsActive)
+	sysDomain, err := h.systemDomainService.ToggleDomainStatus(c.Request.Context(), domainID, req.IsActive)
 	if err != nil {
 		switch err {
 		case service.ErrSystemDomainNotFound:
@@ -393,6 +552,43 @@ func (h *AdminHandler) ToggleSystemDomainStatus(c *gin.Context) {
 	Success(c, sysDomain)
 }
 
+// SetSystemDomainPublicInboxesRequest 设置域名公开收件箱请求
+type SetSystemDomainPublicInboxesRequest struct {
+	AllowPublicInboxes bool `json:"allowPublicInboxes"`
+}
+
+// SetSystemDomainPublicInboxes godoc
+// @Summary 设置域名是否允许公开收件箱
+// @Description 允许后，创建邮箱时可指定 public=true 直接创建公开收件箱；已有的公开收件箱不受影响（需要超级管理员权限）
+// @Tags Admin - System Domains
+// @Accept json
+// @Produce json
+// @Param id path string true "域名ID"
+// @Param request body SetSystemDomainPublicInboxesRequest true "设置"
+// @Success 200 {object} domain.SystemDomain
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/admin/domains/{id}/public-inboxes [put]
+func (h *AdminHandler) SetSystemDomainPublicInboxes(c *gin.Context) {
+	var req SetSystemDomainPublicInboxesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	sysDomain, err := h.systemDomainService.SetAllowPublicInboxes(c.Request.Context(), c.Param("id"), req.AllowPublicInboxes)
+	if err != nil {
+		if err == service.ErrSystemDomainNotFound {
+			NotFound(c, MsgDomainNotFoundAdmin)
+			return
+		}
+		InternalError(c, MsgDomainUpdateFailed)
+		return
+	}
+
+	Success(c, sysDomain)
+}
+
 // SetDefaultSystemDomain godoc
 // @Summary 设置默认系统域名
 // @Description 将指定域名设为默认域名（需要超级管理员权限）
@@ -405,7 +601,7 @@ func (h *AdminHandler) ToggleSystemDomainStatus(c *gin.Context) {
 func (h *AdminHandler) SetDefaultSystemDomain(c *gin.Context) {
 	domainID := c.Param("id")
 
-	err := h.systemDomainService.SetDefaultDomain(domainID)
+	err := h.systemDomainService.SetDefaultDomain(c.Request.Context(), domainID)
 	if err != nil {
 		switch err {
 		case service.ErrSystemDomainNotFound:
@@ -446,11 +642,13 @@ func (h *AdminHandler) RecoverSystemDomain(c *gin.Context) {
 		return
 	}
 
-	sysDomain, err := h.systemDomainService.RecoverSystemDomain(req.Domain, userID)
+	sysDomain, err := h.systemDomainService.RecoverSystemDomain(c.Request.Context(), req.Domain, userID)
 	if err != nil {
 		switch err {
 		case service.ErrInvalidSystemDomain:
 			BadRequest(c, "无效的域名格式")
+		case service.ErrSystemDomainOwnedByUser:
+			Conflict(c, GetErrorMessage(err))
 		default:
 			UnprocessableEntity(c, err.Error())
 		}
@@ -460,6 +658,59 @@ func (h *AdminHandler) RecoverSystemDomain(c *gin.Context) {
 	Success(c, sysDomain)
 }
 
+// ClaimUserDomainRequest 用户域名转系统域名请求
+type ClaimUserDomainRequest struct {
+	Domain          string `json:"domain" binding:"required"`
+	MailboxStrategy string `json:"mailboxStrategy" binding:"omitempty,oneof=keep expire-in-30d"`
+	Notes           string `json:"notes"`
+}
+
+// ClaimDomainFromUser godoc
+// @Summary 将用户域名转为系统域名
+// @Description 将已被用户添加的域名转换为系统域名并通知原所有者，已有邮箱可保留或 30 天后过期（需要超级管理员权限）
+// @Tags Admin - System Domains
+// @Accept json
+// @Produce json
+// @Param request body ClaimUserDomainRequest true "转换信息"
+// @Success 200 {object} service.ClaimFromUserResult
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/admin/domains/claim-from-user [post]
+func (h *AdminHandler) ClaimDomainFromUser(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req ClaimUserDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	result, err := h.systemDomainService.ClaimFromUser(c.Request.Context(), service.ClaimFromUserInput{
+		Domain:          req.Domain,
+		ClaimedBy:       userID,
+		MailboxStrategy: service.ClaimMailboxStrategy(req.MailboxStrategy),
+		Notes:           req.Notes,
+	})
+	if err != nil {
+		switch err {
+		case service.ErrInvalidSystemDomain:
+			BadRequest(c, "无效的域名格式")
+		case service.ErrInvalidClaimStrategy:
+			BadRequest(c, GetErrorMessage(err))
+		case service.ErrUserDomainNotFound:
+			NotFound(c, GetErrorMessage(err))
+		case service.ErrSystemDomainAlreadyExists:
+			Conflict(c, MsgDomainAlreadyExists)
+		default:
+			InternalError(c, MsgDomainAddFailedAdmin)
+		}
+		return
+	}
+
+	Success(c, result)
+}
+
 // DeleteSystemDomain godoc
 // @Summary 删除系统域名
 // @Description 删除系统域名（需要超级管理员权限）
@@ -473,7 +724,12 @@ func (h *AdminHandler) RecoverSystemDomain(c *gin.Context) {
 func (h *AdminHandler) DeleteSystemDomain(c *gin.Context) {
 	domainID := c.Param("id")
 
-	err := h.systemDomainService.DeleteSystemDomain(domainID)
+	var before map[string]any
+	if existing, err := h.systemDomainService.GetSystemDomain(c.Request.Context(), domainID); err == nil {
+		before = service.AuditSnapshot(existing)
+	}
+
+	err := h.systemDomainService.DeleteSystemDomain(c.Request.Context(), domainID)
 	if err != nil {
 		switch err {
 		case service.ErrSystemDomainNotFound:
@@ -488,6 +744,8 @@ func (h *AdminHandler) DeleteSystemDomain(c *gin.Context) {
 		return
 	}
 
+	audit := middleware.AuditEntry(c)
+	audit.Action, audit.TargetType, audit.Changes = service.AuditDomainDelete, domain.AuditTargetDomain, service.AuditDiff(before, nil)
 	NoContent(c)
 }
 
@@ -501,7 +759,7 @@ func (h *AdminHandler) DeleteSystemDomain(c *gin.Context) {
 // @Success 200 {object} domain.SystemStatistics
 // @Router /v1/admin/statistics [get]
 func (h *AdminHandler) GetStatistics(c *gin.Context) {
-	stats, err := h.adminService.GetStatistics()
+	stats, err := h.adminService.GetStatistics(c.Request.Context())
 	if err != nil {
 		InternalError(c, MsgStatisticsGetFailed)
 		return
@@ -522,7 +780,7 @@ func (h *AdminHandler) GetStatistics(c *gin.Context) {
 func (h *AdminHandler) GetUserQuota(c *gin.Context) {
 	userID := c.Param("id")
 
-	quota, err := h.adminService.GetUserQuota(userID)
+	quota, err := h.adminService.GetUserQuota(c.Request.Context(), userID)
 	if err != nil {
 		if err == service.ErrAdminUserNotFound {
 			NotFound(c, MsgUserNotFound)
@@ -541,6 +799,7 @@ type UpdateUserQuotaRequest struct {
 	MaxMessagesPerMailbox   int `json:"maxMessagesPerMailbox"`
 	MaxAPIRequestsPerMinute int `json:"maxApiRequestsPerMinute"`
 	MaxConcurrentRequests   int `json:"maxConcurrentRequests"`
+	MaxMailboxLifetimeHours int `json:"maxMailboxLifetimeHours"`
 }
 
 // UpdateUserQuota godoc
@@ -570,9 +829,15 @@ func (h *AdminHandler) UpdateUserQuota(c *gin.Context) {
 		MaxMessagesPerMailbox:   req.MaxMessagesPerMailbox,
 		MaxAPIRequestsPerMinute: req.MaxAPIRequestsPerMinute,
 		MaxConcurrentRequests:   req.MaxConcurrentRequests,
+		MaxMailboxLifetimeHours: req.MaxMailboxLifetimeHours,
+	}
+
+	var before map[string]any
+	if existing, err := h.adminService.GetUserQuota(c.Request.Context(), userID); err == nil {
+		before = service.AuditSnapshot(existing)
 	}
 
-	err := h.adminService.UpdateUserQuota(userID, quota)
+	err := h.adminService.UpdateUserQuota(c.Request.Context(), userID, quota)
 	if err != nil {
 		if err == service.ErrAdminUserNotFound {
 			NotFound(c, MsgUserNotFound)
@@ -582,5 +847,7 @@ func (h *AdminHandler) UpdateUserQuota(c *gin.Context) {
 		return
 	}
 
+	audit := middleware.AuditEntry(c)
+	audit.Action, audit.TargetType, audit.Changes = service.AuditUserQuotaUpdate, domain.AuditTargetUser, service.AuditDiff(before, quota)
 	SuccessWithMsg(c, "配额更新成功", nil)
 }
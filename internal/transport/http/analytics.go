@@ -0,0 +1,88 @@
+package httptransport
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/analytics"
+)
+
+// AnalyticsHandler 管理员使用情况统计处理器
+type AnalyticsHandler struct {
+	collector *analytics.Collector
+}
+
+// NewAnalyticsHandler 创建使用情况统计处理器
+func NewAnalyticsHandler(collector *analytics.Collector) *AnalyticsHandler {
+	return &AnalyticsHandler{collector: collector}
+}
+
+// Query godoc
+// @Summary 使用情况统计
+// @Description 按小时或按天返回指标的时间序列（只含汇总计数，最多延迟一个写入间隔）。指标：mailboxes.created.domain、mailboxes.created.auth（guest/user）、mailboxes.active（每日）、messages.received.domain、messages.received.sender（发件人每日轮换盐的哈希，匿名模式下没有数据）、features.used（search/export/webhook_created）、websocket.peak。format=csv 时以 CSV 下载
+// @Tags Admin
+// @Produce json,text/csv
+// @Security BearerAuth
+// @Param metric query string true "指标"
+// @Param interval query string false "粒度：hour 或 day（默认 day）"
+// @Param from query string false "开始时间（RFC3339 或 2006-01-02，默认按天为 30 天前、按小时为 24 小时前）"
+// @Param to query string false "结束时间（RFC3339 或 2006-01-02，默认当前时间）"
+// @Param format query string false "json（默认）或 csv"
+// @Success 200 {object} Response{data=analytics.Report}
+// @Failure 400 {object} Response
+// @Router /v1/admin/analytics [get]
+func (h *AnalyticsHandler) Query(c *gin.Context) {
+	from, errFrom := parseAnalyticsTime(c.Query("from"))
+	to, errTo := parseAnalyticsTime(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		BadRequest(c, GetErrorMessage(analytics.ErrInvalidTimeRange))
+		return
+	}
+
+	report, err := h.collector.Query(c.Request.Context(), analytics.Query{
+		Metric:   c.Query("metric"),
+		Interval: c.Query("interval"),
+		From:     from,
+		To:       to,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, analytics.ErrUnknownMetric), errors.Is(err, analytics.ErrInvalidInterval),
+			errors.Is(err, analytics.ErrInvalidTimeRange), errors.Is(err, analytics.ErrTimeRangeTooLong):
+			BadRequest(c, GetErrorMessage(err))
+		default:
+			InternalError(c, MsgAnalyticsQueryFailed)
+		}
+		return
+	}
+
+	if c.Query("format") != "csv" {
+		Success(c, report)
+		return
+	}
+
+	filename := fmt.Sprintf("analytics-%s-%s.csv", report.Metric, report.From.Format("20060102"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	// 已开始写响应，出错时只能中断连接
+	if err := analytics.WriteCSV(c.Writer, report); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// parseAnalyticsTime 解析 RFC3339 时间或 UTC 日期，空值返回零值
+func parseAnalyticsTime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
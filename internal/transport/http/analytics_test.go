@@ -0,0 +1,82 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/analytics"
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestAnalyticsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	collector := analytics.NewCollector(memory.NewStore(time.Hour), config.AnalyticsConfig{Enabled: true}, nil)
+	now := time.Date(2026, 3, 2, 12, 30, 0, 0, time.UTC)
+	collector.SetClock(func() time.Time { return now })
+	collector.NotifyMailboxCreated(&domain.Mailbox{ID: "mb-1", Domain: "temp.mail"})
+
+	router := gin.New()
+	router.GET("/v1/admin/analytics", NewAnalyticsHandler(collector).Query)
+	router.GET("/search", middleware.FeatureUsage(collector, analytics.FeatureSearch), func(c *gin.Context) {
+		if c.Query("fail") != "" {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, get("/search").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/search?fail=1").Code)
+	require.NoError(t, collector.Flush(t.Context()))
+
+	t.Run("按天返回时间序列", func(t *testing.T) {
+		w := get("/v1/admin/analytics?metric=mailboxes.created.domain&from=2026-03-01&to=2026-03-03")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Data analytics.Report `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Data.Series, 1)
+		assert.Equal(t, "temp.mail", resp.Data.Series[0].Dimension)
+		require.Len(t, resp.Data.Series[0].Points, 2)
+		assert.Zero(t, resp.Data.Series[0].Points[0].Value)
+		assert.Equal(t, int64(1), resp.Data.Series[0].Points[1].Value)
+	})
+
+	t.Run("CSV 下载只统计成功的调用", func(t *testing.T) {
+		w := get("/v1/admin/analytics?metric=features.used&interval=hour&from=2026-03-02T11:00:00Z&to=2026-03-02T13:00:00Z&format=csv")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "analytics-features.used-20260302.csv")
+		assert.Equal(t, strings.Join([]string{
+			"time,metric,dimension,value",
+			"2026-03-02T11:00:00Z,features.used,search,0",
+			"2026-03-02T12:00:00Z,features.used,search,1",
+			"",
+		}, "\n"), w.Body.String())
+	})
+
+	t.Run("参数无效", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("/v1/admin/analytics?metric=unknown").Code)
+		assert.Equal(t, http.StatusBadRequest, get("/v1/admin/analytics?metric=features.used&from=yesterday").Code)
+		assert.Equal(t, http.StatusBadRequest, get("/v1/admin/analytics?metric=features.used&interval=hour&from=2025-01-01").Code)
+	})
+}
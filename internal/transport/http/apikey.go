@@ -76,7 +76,7 @@ func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
 	}
 
 	// 创建API Key
-	apiKey, err := h.apiKeyService.CreateAPIKey(service.CreateAPIKeyInput{
+	apiKey, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), service.CreateAPIKeyInput{
 		UserID:    userID.(string),
 		Name:      req.Name,
 		ExpiresIn: expiresIn,
@@ -117,7 +117,7 @@ func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
 	}
 
 	// 获取API Key列表
-	apiKeys, err := h.apiKeyService.ListAPIKeys(userID.(string))
+	apiKeys, err := h.apiKeyService.ListAPIKeys(c.Request.Context(), userID.(string))
 	if err != nil {
 		InternalError(c, MsgAPIKeyListFailed)
 		return
@@ -165,7 +165,7 @@ func (h *APIKeyHandler) GetAPIKey(c *gin.Context) {
 	keyID := c.Param("id")
 
 	// 获取API Key
-	apiKey, err := h.apiKeyService.GetAPIKey(keyID)
+	apiKey, err := h.apiKeyService.GetAPIKey(c.Request.Context(), keyID)
 	if err != nil {
 		NotFound(c, MsgAPIKeyNotFound)
 		return
@@ -209,7 +209,7 @@ func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
 	keyID := c.Param("id")
 
 	// 删除API Key
-	err := h.apiKeyService.DeleteAPIKey(userID.(string), keyID)
+	err := h.apiKeyService.DeleteAPIKey(c.Request.Context(), userID.(string), keyID)
 	if err != nil {
 		if err == service.ErrAPIKeyNotFound {
 			NotFound(c, MsgAPIKeyNotFound)
@@ -0,0 +1,51 @@
+package httptransport
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/security"
+)
+
+// serveAttachment 以下载方式返回附件内容
+//
+// 按入库时判断的实际类型返回，不信任发件方声明的类型；可能被浏览器执行的类型（HTML、脚本、
+// 可执行文件）一律返回 application/octet-stream，并禁止浏览器再次猜测类型。
+//
+// 内容可定位时（内存、本地文件、对象存储）支持 Range 请求（断点续传、分段下载），
+// 否则按声明大小流式返回；大小未知时使用分块传输，不把附件整体读入内存。
+func serveAttachment(c *gin.Context, attachment *domain.Attachment, content io.Reader) {
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", "attachment; filename=\""+attachment.Filename+"\"")
+	if attachment.SHA256 != "" {
+		c.Header("ETag", "\""+attachment.SHA256+"\"") // 供 If-Range 判断续传的是否同一内容
+	}
+
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		c.Header("Content-Type", servedContentType(attachment))
+		http.ServeContent(c.Writer, c.Request, "", time.Time{}, seeker)
+		return
+	}
+
+	size := attachment.Size
+	if size <= 0 {
+		size = -1 // 不设置 Content-Length，按分块传输
+	}
+	c.DataFromReader(http.StatusOK, size, servedContentType(attachment), content, nil)
+}
+
+// servedContentType 附件下载时使用的类型（旧数据没有实际类型时退回声明类型）
+func servedContentType(attachment *domain.Attachment) string {
+	contentType := attachment.DetectedContentType
+	if contentType == "" {
+		contentType = attachment.ContentType
+	}
+	if contentType == "" || security.IsActiveContentType(contentType) || security.IsActiveContentType(attachment.ContentType) {
+		return "application/octet-stream"
+	}
+	return contentType
+}
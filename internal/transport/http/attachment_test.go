@@ -0,0 +1,103 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestAttachmentContentType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "qa@temp.mail", LocalPart: "qa", Domain: "temp.mail", CreatedAt: time.Now(),
+	}))
+	messages := service.NewMessageService(store)
+	msg, err := messages.Create(t.Context(), service.CreateMessageInput{
+		MailboxID: "mb-1",
+		From:      "sender@example.com",
+		Subject:   "attachments",
+		Attachments: []*domain.Attachment{
+			{ID: "att-html", Filename: "photo.png", ContentType: "image/png", Content: []byte("<html><script>alert(1)</script></html>")},
+			{ID: "att-pdf", Filename: "report.pdf", ContentType: "application/octet-stream", Content: []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")},
+			{ID: "att-virus", Filename: "invoice.exe", ContentType: "application/octet-stream", Content: []byte("MZ"), Infected: true, Threat: "Win.Trojan.Agent"},
+		},
+	})
+	require.NoError(t, err)
+
+	h := &Handler{messages: messages}
+	router := gin.New()
+	router.GET("/v1/mailboxes/:id/messages/:messageId", h.getMessage)
+	router.GET("/v1/mailboxes/:id/messages/:messageId/attachments/:attachmentId", h.downloadAttachment)
+
+	base := "/v1/mailboxes/mb-1/messages/" + msg.ID
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("伪装成图片的 HTML 按二进制下载", func(t *testing.T) {
+		w := get(base + "/attachments/att-html")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+		assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	})
+
+	t.Run("按实际类型返回声明为通用二进制的 PDF", func(t *testing.T) {
+		w := get(base + "/attachments/att-pdf")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("邮件详情标记类型不一致", func(t *testing.T) {
+		w := get(base)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data messageResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Data.Attachments, 3)
+
+		byID := map[string]attachmentInfo{}
+		for _, att := range resp.Data.Attachments {
+			byID[att.ID] = att
+		}
+		assert.Equal(t, "image/png", byID["att-html"].ContentType)
+		assert.Equal(t, "text/html", byID["att-html"].DetectedContentType)
+		assert.True(t, byID["att-html"].ContentTypeMismatch)
+		assert.Equal(t, "application/pdf", byID["att-pdf"].DetectedContentType)
+		assert.False(t, byID["att-pdf"].ContentTypeMismatch)
+		assert.True(t, byID["att-virus"].Infected)
+		assert.Equal(t, "Win.Trojan.Agent", byID["att-virus"].Threat)
+	})
+
+	t.Run("按 Range 返回部分内容", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, base+"/attachments/att-pdf", nil)
+		req.Header.Set("Range", "bytes=0-7")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "%PDF-1.4", w.Body.String())
+		assert.Equal(t, "bytes 0-7/15", w.Header().Get("Content-Range"))
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "report.pdf")
+	})
+
+	t.Run("检出病毒的附件禁止下载", func(t *testing.T) {
+		w := get(base + "/attachments/att-virus")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
@@ -0,0 +1,165 @@
+package httptransport
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+)
+
+// AuditLogHandler 管理操作审计记录查询
+type AuditLogHandler struct {
+	audit *service.AuditService
+}
+
+// NewAuditLogHandler 创建审计记录处理器
+func NewAuditLogHandler(audit *service.AuditService) *AuditLogHandler {
+	return &AuditLogHandler{audit: audit}
+}
+
+// List godoc
+// @Summary 查询审计记录
+// @Description 按操作者、操作、目标和时间范围分页查询管理操作审计记录（新记录在前，需要超级管理员权限）
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param actorId query string false "操作者用户ID"
+// @Param action query string false "操作，如 user.update、domain.delete、config.update"
+// @Param targetType query string false "目标类型（user、domain、config）"
+// @Param targetId query string false "目标ID"
+// @Param since query string false "起始时间（RFC3339，含）"
+// @Param until query string false "结束时间（RFC3339，不含）"
+// @Param limit query int false "每页数量（最大200）" default(50)
+// @Param offset query int false "偏移量" default(0)
+// @Success 200 {object} Response{data=service.AuditLogPage}
+// @Failure 400 {object} Response
+// @Router /v1/admin/audit-logs [get]
+func (h *AuditLogHandler) List(c *gin.Context) {
+	filter := domain.AuditLogFilter{
+		ActorID:    c.Query("actorId"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("targetType"),
+		TargetID:   c.Query("targetId"),
+	}
+	var err error
+	if filter.Since, err = parseAuditTime(c.Query("since")); err != nil {
+		BadRequest(c, GetErrorMessage(service.ErrAuditQueryInvalid))
+		return
+	}
+	if filter.Until, err = parseAuditTime(c.Query("until")); err != nil {
+		BadRequest(c, GetErrorMessage(service.ErrAuditQueryInvalid))
+		return
+	}
+	if filter.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "0")); err != nil {
+		BadRequest(c, GetErrorMessage(service.ErrAuditQueryInvalid))
+		return
+	}
+	if filter.Offset, err = strconv.Atoi(c.DefaultQuery("offset", "0")); err != nil {
+		BadRequest(c, GetErrorMessage(service.ErrAuditQueryInvalid))
+		return
+	}
+
+	page, err := h.audit.List(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, service.ErrAuditQueryInvalid) {
+			BadRequest(c, GetErrorMessage(err))
+			return
+		}
+		InternalError(c, MsgAuditLogListFailed)
+		return
+	}
+
+	Success(c, page)
+}
+
+// parseAuditTime 解析 RFC3339 时间（空字符串为零值）
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// ImpersonationHandler 代登录（客服以用户身份只读排查问题）
+type ImpersonationHandler struct {
+	impersonation *service.ImpersonationService
+}
+
+// NewImpersonationHandler 创建代登录处理器
+func NewImpersonationHandler(impersonation *service.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{impersonation: impersonation}
+}
+
+// ImpersonateRequest 代登录请求
+type ImpersonateRequest struct {
+	Reason    string `json:"reason" binding:"required"` // 原因（写入审计记录）
+	ExpiresIn string `json:"expiresIn,omitempty"`       // 有效期，如 "15m"（默认 15 分钟，最长 1 小时）
+}
+
+// ImpersonateResponse 代登录令牌
+type ImpersonateResponse struct {
+	AccessToken string       `json:"accessToken"`
+	ExpiresAt   time.Time    `json:"expiresAt"`
+	User        *domain.User `json:"user"`
+}
+
+// Impersonate godoc
+// @Summary 代用户登录
+// @Description 签发以目标用户身份访问的短期只读令牌（只能发起 GET 请求，没有刷新令牌），用于排查用户的邮箱列表等问题。不能代登录管理员或已禁用的用户，每次签发都写入审计记录
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用户ID"
+// @Param request body ImpersonateRequest true "原因和有效期"
+// @Success 201 {object} Response{data=ImpersonateResponse}
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/admin/users/{id}/impersonate [post]
+func (h *ImpersonationHandler) Impersonate(c *gin.Context) {
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, GetErrorMessage(service.ErrImpersonationReasonRequired))
+		return
+	}
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		duration, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil {
+			BadRequest(c, GetErrorMessage(service.ErrImpersonationTTLInvalid))
+			return
+		}
+		ttl = duration
+	}
+
+	token, user, err := h.impersonation.Start(c.Request.Context(), service.ImpersonationInput{
+		ActorID:  c.GetString("userID"),
+		TargetID: c.Param("id"),
+		TTL:      ttl,
+		Reason:   req.Reason,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrImpersonationTTLInvalid), errors.Is(err, service.ErrImpersonationReasonRequired):
+			BadRequest(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrImpersonateSelf), errors.Is(err, service.ErrImpersonateAdmin), errors.Is(err, service.ErrImpersonateInactive):
+			Forbidden(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrUserNotFound):
+			NotFound(c, MsgUserNotFound)
+		default:
+			InternalError(c, MsgImpersonateFailed)
+		}
+		return
+	}
+
+	audit := middleware.AuditEntry(c)
+	audit.Action, audit.TargetType, audit.Note = service.AuditUserImpersonate, domain.AuditTargetUser, req.Reason
+	audit.Changes = []domain.AuditChange{{Field: "expiresAt", To: token.ExpiresAt}}
+	Created(c, ImpersonateResponse{AccessToken: token.AccessToken, ExpiresAt: token.ExpiresAt, User: user})
+}
@@ -0,0 +1,129 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestAuditAndImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	for _, user := range []*domain.User{
+		{ID: "admin-1", Email: "admin@example.com", Username: "admin", Role: domain.RoleAdmin, Tier: domain.TierFree, IsActive: true},
+		{ID: "user-1", Email: "user@example.com", Username: "user", Role: domain.RoleUser, Tier: domain.TierFree, IsActive: true},
+	} {
+		require.NoError(t, store.CreateUser(t.Context(), user))
+	}
+	jwtManager := jwtpkg.NewManager("test-secret-0123456789abcdefghijklmnop", "test", time.Hour, 24*time.Hour)
+	jwtAuth := middleware.NewJWTAuth(jwtManager)
+	audit := service.NewAuditService(store)
+	adminHandler := NewAdminHandler(service.NewAdminService(store, &domain.Config{}), nil)
+	impersonationHandler := NewImpersonationHandler(service.NewImpersonationService(store, jwtManager))
+	auditHandler := NewAuditLogHandler(audit)
+
+	router := gin.New()
+	admin := router.Group("/v1/admin", jwtAuth.RequireAuth(), middleware.AdminAudit(audit, nil))
+	admin.PATCH("/users/:id", adminHandler.UpdateUser)
+	admin.POST("/users/:id/impersonate", impersonationHandler.Impersonate)
+	admin.GET("/audit-logs", auditHandler.List)
+	user := router.Group("/v1/user", jwtAuth.RequireAuth())
+	user.GET("/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"userID": c.GetString("userID"), "impersonatorID": c.GetString("impersonatorID")})
+	})
+	user.POST("/mailboxes", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	adminTokens, err := jwtManager.GenerateTokenPair(t.Context(), "admin-1", "admin@example.com", string(domain.TierFree))
+	require.NoError(t, err)
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	listLogs := func(query string) service.AuditLogPage {
+		w := do(http.MethodGet, "/v1/admin/audit-logs"+query, adminTokens.AccessToken, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data service.AuditLogPage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	t.Run("记录用户修改及字段变更", func(t *testing.T) {
+		w := do(http.MethodPatch, "/v1/admin/users/user-1", adminTokens.AccessToken, `{"tier":"pro"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		page := listLogs("?action=user.update&targetId=user-1")
+		require.Len(t, page.Items, 1)
+		entry := page.Items[0]
+		assert.Equal(t, "admin-1", entry.ActorID)
+		assert.Equal(t, "admin@example.com", entry.ActorEmail)
+		assert.Equal(t, domain.AuditTargetUser, entry.TargetType)
+		require.NotEmpty(t, entry.Changes)
+		var tier *domain.AuditChange
+		for i := range entry.Changes {
+			if entry.Changes[i].Field == "tier" {
+				tier = &entry.Changes[i]
+			}
+		}
+		require.NotNil(t, tier)
+		assert.Equal(t, "free", tier.From)
+		assert.Equal(t, "pro", tier.To)
+	})
+
+	t.Run("失败的操作不记录", func(t *testing.T) {
+		w := do(http.MethodPatch, "/v1/admin/users/missing", adminTokens.AccessToken, `{"tier":"pro"}`)
+		require.Equal(t, http.StatusNotFound, w.Code)
+		assert.Zero(t, listLogs("?targetId=missing").Total)
+	})
+
+	t.Run("查询条件无效", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/v1/admin/audit-logs?since=yesterday", adminTokens.AccessToken, "").Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/v1/admin/audit-logs?limit=-1", adminTokens.AccessToken, "").Code)
+	})
+
+	t.Run("代登录令牌只读", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/v1/admin/users/user-1/impersonate", adminTokens.AccessToken, `{}`).Code)
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/v1/admin/users/admin-1/impersonate", adminTokens.AccessToken, `{"reason":"self"}`).Code)
+
+		w := do(http.MethodPost, "/v1/admin/users/user-1/impersonate", adminTokens.AccessToken, `{"reason":"ticket #42: mailbox list empty","expiresIn":"10m"}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Data ImpersonateResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), resp.Data.ExpiresAt, time.Minute)
+
+		w = do(http.MethodGet, "/v1/user/me", resp.Data.AccessToken, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"userID":"user-1"`)
+		assert.Contains(t, w.Body.String(), `"impersonatorID":"admin-1"`)
+
+		w = do(http.MethodPost, "/v1/user/mailboxes", resp.Data.AccessToken, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "IMPERSONATION_READ_ONLY")
+
+		page := listLogs("?action=user.impersonate")
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, "user-1", page.Items[0].TargetID)
+		assert.Equal(t, "ticket #42: mailbox list empty", page.Items[0].Note)
+	})
+}
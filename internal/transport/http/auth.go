@@ -1,6 +1,7 @@
 package httptransport
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -12,9 +13,10 @@ import (
 
 // AuthHandler 处理认证相关的 HTTP 请求
 type AuthHandler struct {
-	authService *auth.Service   // 认证业务服务
-	jwtManager  *jwtpkg.Manager // JWT 令牌管理器
-	log         *zap.Logger     // 结构化日志记录器
+	authService *auth.Service        // 认证业务服务
+	jwtManager  *jwtpkg.Manager      // JWT 令牌管理器
+	sessions    *auth.SessionService // 登录会话（可选，未设置时令牌无状态）
+	log         *zap.Logger          // 结构化日志记录器
 }
 
 // NewAuthHandler 创建新的认证处理器实例
@@ -33,6 +35,12 @@ func NewAuthHandler(authService *auth.Service, jwtManager *jwtpkg.Manager) *Auth
 	}
 }
 
+// 登录被拒绝时返回的原因代码
+const (
+	ReasonAccountLocked   = "ACCOUNT_LOCKED"
+	ReasonTooManyAttempts = "TOO_MANY_ATTEMPTS"
+)
+
 type registerRequest struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
@@ -56,12 +64,18 @@ type authResponse struct {
 }
 
 type userResponse struct {
-	ID              string `json:"id"`
-	Email           string `json:"email"`
-	Username        string `json:"username,omitempty"`
-	Tier            string `json:"tier"`
-	IsActive        bool   `json:"isActive"`
-	IsEmailVerified bool   `json:"isEmailVerified"`
+	ID               string `json:"id"`
+	Email            string `json:"email"`
+	Username         string `json:"username,omitempty"`
+	Tier             string `json:"tier"`
+	IsActive         bool   `json:"isActive"`
+	IsEmailVerified  bool   `json:"isEmailVerified"`
+	TwoFactorEnabled bool   `json:"twoFactorEnabled"`
+}
+
+// appPasswordResponse 应用专用密码（IMAP 登录时与邮箱地址配合使用）
+type appPasswordResponse struct {
+	AppPassword string `json:"appPassword"`
 }
 
 // Register 处理用户注册请求
@@ -84,7 +98,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// 注册用户
-	user, err := h.authService.Register(auth.RegisterInput{
+	user, err := h.authService.Register(c.Request.Context(), auth.RegisterInput{
 		Email:    req.Email,
 		Password: req.Password,
 		Username: req.Username,
@@ -106,7 +120,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// 生成令牌
-	tokens, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email, string(user.Tier))
+	tokens, err := issueTokens(c, h.sessions, h.jwtManager, user)
 	if err != nil {
 		h.log.Error("failed to generate tokens", zap.Error(err))
 		InternalError(c, "生成令牌失败")
@@ -140,10 +154,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body loginRequest true "登录凭证"
-// @Success 200 {object} authResponse "登录成功"
+// @Success 200 {object} authResponse "登录成功（已启用两步验证时返回 twoFactorChallengeResponse，需调用 /v1/auth/2fa/login）"
 // @Failure 400 {object} Response "请求参数错误"
 // @Failure 401 {object} Response "邮箱或密码错误"
 // @Failure 403 {object} Response "账户已被禁用"
+// @Failure 423 {object} Response "连续登录失败，账户已临时锁定（data.reason=ACCOUNT_LOCKED）"
+// @Failure 429 {object} Response "该 IP 登录失败次数过多（data.reason=TOO_MANY_ATTEMPTS）"
 // @Failure 500 {object} Response "服务器内部错误"
 // @Router /v1/auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -154,16 +170,21 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// 验证登录
-    user, err := h.authService.Login(auth.LoginInput{
+    user, err := h.authService.Login(c.Request.Context(), auth.LoginInput{
         Identifier: strings.TrimSpace(req.Username),
         Password:   req.Password,
+        IP:         c.ClientIP(),
+        UserAgent:  c.Request.UserAgent(),
     })
 
 	if err != nil {
-		switch err {
-		case auth.ErrInvalidCredentials:
+		if respondLoginThrottled(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
 			Unauthorized(c, MsgInvalidCredentials)
-		case auth.ErrUserInactive:
+		case errors.Is(err, auth.ErrUserInactive):
 			Forbidden(c, "账户已被禁用")
 		default:
 			h.log.Error("failed to login", zap.Error(err))
@@ -172,8 +193,14 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// 启用了两步验证：密码正确后只返回质询令牌，输入验证码后由 /v1/auth/2fa/login 签发令牌
+	if user.TOTPEnabled {
+		h.respondTwoFactorChallenge(c, user.ID)
+		return
+	}
+
 	// 生成令牌
-	tokens, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email, string(user.Tier))
+	tokens, err := issueTokens(c, h.sessions, h.jwtManager, user)
 	if err != nil {
 		h.log.Error("failed to generate tokens", zap.Error(err))
 		InternalError(c, "生成令牌失败")
@@ -200,16 +227,17 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
-// Refresh 刷新访问令牌
-// @Summary 刷新访问令牌
-// @Description 使用刷新令牌获取新的访问令牌，避免重新登录
+// Refresh 刷新令牌
+// @Summary 刷新令牌
+// @Description 使用刷新令牌换取新的访问令牌和刷新令牌，旧刷新令牌随即失效；再次出示已被轮换掉的刷新令牌时整个会话被注销
 // @Tags 认证
 // @Accept json
 // @Produce json
 // @Param request body refreshRequest true "包含刷新令牌的请求"
-// @Success 200 {object} object{accessToken=string,expiresIn=int} "新的访问令牌"
+// @Success 200 {object} object{accessToken=string,refreshToken=string,expiresIn=int} "新的令牌对"
 // @Failure 400 {object} Response "请求参数错误"
-// @Failure 401 {object} Response "刷新令牌无效或已过期"
+// @Failure 401 {object} Response "刷新令牌无效、已过期或已被使用"
+// @Failure 403 {object} Response "账户已被禁用"
 // @Failure 500 {object} Response "服务器内部错误"
 // @Router /v1/auth/refresh [post]
 func (h *AuthHandler) Refresh(c *gin.Context) {
@@ -219,25 +247,44 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	// 刷新访问令牌
-	accessToken, err := h.jwtManager.RefreshAccessToken(req.RefreshToken)
-	if err != nil {
-		switch err {
-		case jwtpkg.ErrInvalidToken:
-			Unauthorized(c, "刷新令牌无效")
-		case jwtpkg.ErrExpiredToken:
-			Unauthorized(c, MsgTokenExpired)
-		default:
-			h.log.Error("failed to refresh token", zap.Error(err))
-			InternalError(c, "刷新令牌失败")
+	// 未启用会话时只续期访问令牌
+	if h.sessions == nil {
+		accessToken, err := h.jwtManager.RefreshAccessToken(c.Request.Context(), req.RefreshToken)
+		if err != nil {
+			h.respondRefreshError(c, err)
+			return
 		}
+		Success(c, gin.H{
+			"accessToken": accessToken,
+			"expiresIn":   int64(15 * 60), // 15 分钟
+		})
 		return
 	}
 
-	Success(c, gin.H{
-		"accessToken": accessToken,
-		"expiresIn":   int64(15 * 60), // 15 分钟
-	})
+	tokens, err := h.sessions.Refresh(c.Request.Context(), req.RefreshToken, sessionClient(c))
+	if err != nil {
+		h.respondRefreshError(c, err)
+		return
+	}
+	Success(c, tokens)
+}
+
+// respondRefreshError 刷新令牌失败的错误映射
+func (h *AuthHandler) respondRefreshError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, jwtpkg.ErrInvalidToken), errors.Is(err, auth.ErrInvalidRefreshToken):
+		Unauthorized(c, MsgRefreshTokenInvalid)
+	case errors.Is(err, jwtpkg.ErrExpiredToken):
+		Unauthorized(c, MsgTokenExpired)
+	case errors.Is(err, auth.ErrRefreshTokenReused):
+		h.log.Warn("refresh token reuse detected, session revoked", zap.String("ip", c.ClientIP()))
+		Unauthorized(c, MsgRefreshTokenReused)
+	case errors.Is(err, auth.ErrUserInactive):
+		Forbidden(c, "账户已被禁用")
+	default:
+		h.log.Error("failed to refresh token", zap.Error(err))
+		InternalError(c, "刷新令牌失败")
+	}
 }
 
 // Me 获取当前用户信息
@@ -259,7 +306,7 @@ func (h *AuthHandler) Me(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authService.GetUserByID(userID.(string))
+	user, err := h.authService.GetUserByID(c.Request.Context(), userID.(string))
 	if err != nil {
 		if err == auth.ErrUserNotFound {
 			NotFound(c, MsgUserNotFound)
@@ -271,15 +318,71 @@ func (h *AuthHandler) Me(c *gin.Context) {
 	}
 
 	Success(c, userResponse{
-		ID:              user.ID,
-		Email:           user.Email,
-		Username:        user.Username,
-		Tier:            string(user.Tier),
-		IsActive:        user.IsActive,
-		IsEmailVerified: user.IsEmailVerified,
+		ID:               user.ID,
+		Email:            user.Email,
+		Username:         user.Username,
+		Tier:             string(user.Tier),
+		IsActive:         user.IsActive,
+		IsEmailVerified:  user.IsEmailVerified,
+		TwoFactorEnabled: user.TOTPEnabled,
 	})
 }
 
+// AppPassword 获取应用专用密码
+// @Summary 获取应用专用密码
+// @Description 由签名密钥派生的固定密码，用于 IMAP 客户端登录本人名下的邮箱（用户名为邮箱地址），签名密钥轮换、重置应用密码、修改密码或注销全部会话后随之变化
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} appPasswordResponse "应用专用密码"
+// @Failure 401 {object} Response "未认证或令牌无效"
+// @Failure 403 {object} Response "代登录令牌不能访问"
+// @Router /v1/auth/me/app-password [get]
+func (h *AuthHandler) AppPassword(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	user, err := h.authService.GetUserByID(c.Request.Context(), userID.(string))
+	if err != nil {
+		NotFound(c, MsgUserNotFound)
+		return
+	}
+	Success(c, appPasswordResponse{AppPassword: h.jwtManager.AppPassword(user.ID, user.AppPasswordGeneration)})
+}
+
+// ResetAppPassword 重置应用专用密码
+// @Summary 重置应用专用密码
+// @Description 之前的应用专用密码立即失效（已登录的 IMAP 客户端需改用新密码），返回新密码
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} appPasswordResponse "新的应用专用密码"
+// @Failure 401 {object} Response "未认证或令牌无效"
+// @Failure 403 {object} Response "代登录令牌不能访问"
+// @Router /v1/auth/me/app-password/reset [post]
+func (h *AuthHandler) ResetAppPassword(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	generation, err := h.authService.ResetAppPassword(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			NotFound(c, MsgUserNotFound)
+			return
+		}
+		h.log.Error("failed to reset app password", zap.Error(err))
+		InternalError(c, MsgUserUpdateFailed)
+		return
+	}
+	Success(c, appPasswordResponse{AppPassword: h.jwtManager.AppPassword(userID, generation)})
+}
+
 // AuthMiddleware JWT 认证中间件
 //
 // 该中间件用于验证请求中的 JWT 令牌，并将用户信息注入到上下文中
@@ -317,7 +420,7 @@ func AuthMiddleware(jwtManager *jwtpkg.Manager) gin.HandlerFunc {
 		token := parts[1]
 
 		// 验证令牌
-		claims, err := jwtManager.ValidateToken(token)
+		claims, err := jwtManager.Authenticate(c.Request.Context(), token)
 		if err != nil {
 			switch err {
 			case jwtpkg.ErrExpiredToken:
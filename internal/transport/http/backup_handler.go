@@ -0,0 +1,102 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// BackupHandler 配置备份API处理器
+type BackupHandler struct {
+	backupService *service.SettingsBackupService
+}
+
+// NewBackupHandler 创建配置备份处理器
+func NewBackupHandler(backupService *service.SettingsBackupService) *BackupHandler {
+	return &BackupHandler{
+		backupService: backupService,
+	}
+}
+
+// ExportSettings godoc
+// @Summary 导出服务器配置
+// @Description 导出系统配置、系统域名和 Webhook 设置（不含用户数据和邮件）。includeSecrets=true 时包含 Webhook 密钥，需要超级管理员权限
+// @Tags Admin - Backup
+// @Produce json
+// @Param includeSecrets query bool false "是否包含 Webhook 密钥"
+// @Success 200 {object} Response{data=service.SettingsBackup}
+// @Failure 403 {object} Response
+// @Failure 500 {object} Response
+// @Router /v1/admin/backup/settings [get]
+func (h *BackupHandler) ExportSettings(c *gin.Context) {
+	includeSecrets := c.Query("includeSecrets") == "true"
+	if includeSecrets && !isSuperAdmin(c) {
+		Forbidden(c, MsgPermissionDenied)
+		return
+	}
+
+	backup, err := h.backupService.Export(c.Request.Context(), includeSecrets)
+	if err != nil {
+		InternalError(c, MsgBackupExportFailed)
+		return
+	}
+
+	Success(c, backup)
+}
+
+// RestoreSettings godoc
+// @Summary 恢复服务器配置
+// @Description 导入配置备份。dryRun=true 时只报告将要创建/更新/冲突的项；否则幂等写入，存在冲突时拒绝（需要超级管理员权限）
+// @Tags Admin - Backup
+// @Accept json
+// @Produce json
+// @Param dryRun query bool false "仅预演，不写入"
+// @Param request body service.SettingsBackup true "配置备份文档"
+// @Success 200 {object} Response{data=service.RestoreReport}
+// @Failure 400 {object} Response
+// @Failure 409 {object} Response{data=service.RestoreReport}
+// @Failure 500 {object} Response
+// @Router /v1/admin/backup/settings/restore [post]
+func (h *BackupHandler) RestoreSettings(c *gin.Context) {
+	var backup service.SettingsBackup
+	if err := c.ShouldBindJSON(&backup); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	dryRun := c.Query("dryRun") == "true"
+	report, err := h.backupService.Restore(c.Request.Context(), &backup, dryRun, c.GetString("userID"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRestoreConflicts):
+			c.JSON(http.StatusConflict, Response{
+				Code: CodeConflict,
+				Msg:  GetErrorMessage(service.ErrRestoreConflicts),
+				Data: report,
+			})
+		case errors.Is(err, service.ErrBackupSchemaMismatch),
+			errors.Is(err, service.ErrBackupVersionMismatch),
+			errors.Is(err, service.ErrInvalidBackup):
+			BadRequest(c, err.Error())
+		default:
+			InternalError(c, MsgBackupRestoreFailed)
+		}
+		return
+	}
+
+	Success(c, report)
+}
+
+// isSuperAdmin 当前用户是否为超级管理员（由 AdminAuth 中间件写入上下文）
+func isSuperAdmin(c *gin.Context) bool {
+	role, ok := c.Get("role")
+	if !ok {
+		return false
+	}
+	userRole, ok := role.(domain.UserRole)
+	return ok && userRole == domain.RoleSuper
+}
@@ -0,0 +1,75 @@
+package httptransport
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// CatchAllHandler 用户域名通配收件API处理器
+type CatchAllHandler struct {
+	catchAllService *service.CatchAllService
+}
+
+// NewCatchAllHandler 创建通配收件处理器
+func NewCatchAllHandler(catchAllService *service.CatchAllService) *CatchAllHandler {
+	return &CatchAllHandler{
+		catchAllService: catchAllService,
+	}
+}
+
+// catchAllSettingsRequest 通配收件设置请求
+type catchAllSettingsRequest struct {
+	Enabled   bool   `json:"enabled"`
+	MailboxID string `json:"mailboxId"` // 通配邮箱，为空时按收件地址自动创建邮箱
+}
+
+// UpdateUserDomainCatchAll godoc
+// @Summary 设置用户域名通配收件
+// @Description 开启后域名切换为通配模式：发往不存在地址的邮件投递到通配邮箱，未指定通配邮箱时以收件地址自动创建邮箱（归域名所有者）。仅已验证的独享或通配模式域名可以开启；关闭后切回独享模式
+// @Tags User Domains
+// @Accept json
+// @Produce json
+// @Param id path string true "域名ID"
+// @Param request body catchAllSettingsRequest true "通配收件设置"
+// @Success 200 {object} Response{data=domain.UserDomain}
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/user/domains/{id}/catch-all [put]
+func (h *CatchAllHandler) UpdateUserDomainCatchAll(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	var req catchAllSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	userDomain, err := h.catchAllService.ConfigureUserDomain(c.Request.Context(), c.Param("id"), userID, domain.CatchAllSettings{
+		Enabled:   req.Enabled,
+		MailboxID: req.MailboxID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDomainNotFound):
+			NotFound(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrNotDomainOwner):
+			Forbidden(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrCatchAllRequiresVerified), errors.Is(err, service.ErrCatchAllMailbox):
+			BadRequest(c, GetErrorMessage(err))
+		default:
+			InternalError(c, MsgCatchAllUpdateFailed)
+		}
+		return
+	}
+
+	Success(c, userDomain)
+}
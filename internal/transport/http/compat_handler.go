@@ -15,7 +15,8 @@ import (
 
 // errorResponse 兼容API错误响应（旧格式）
 type errorResponse struct {
-	Error string `json:"error"`
+	Error  string `json:"error"`
+	Reason string `json:"reason,omitempty"` // 结构化原因，如 ADDRESS_TAKEN
 }
 
 // CompatHandler 兼容API处理器
@@ -23,12 +24,6 @@ type CompatHandler struct {
 	mailboxes *service.MailboxService
 	messages  *service.MessageService
 	aliases   *service.AliasService
-	config    *compatConfig
-}
-
-// compatConfig 兼容API配置
-type compatConfig struct {
-	AllowedDomains []string
 }
 
 // NewCompatHandler 创建兼容API处理器
@@ -36,15 +31,11 @@ func NewCompatHandler(
 	mailboxService *service.MailboxService,
 	messageService *service.MessageService,
 	aliasService *service.AliasService,
-	allowedDomains []string,
 ) *CompatHandler {
 	return &CompatHandler{
 		mailboxes: mailboxService,
 		messages:  messageService,
 		aliases:   aliasService,
-		config: &compatConfig{
-			AllowedDomains: allowedDomains,
-		},
 	}
 }
 
@@ -130,7 +121,7 @@ type attachmentItem struct {
 // @Router /api/config [get]
 func (h *CompatHandler) GetConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, configResponse{
-		Domains: h.config.AllowedDomains,
+		Domains: h.mailboxes.AllowedDomains(), // 系统配置修改后即时生效
 	})
 }
 
@@ -168,7 +159,7 @@ func (h *CompatHandler) GenerateEmail(c *gin.Context) {
 	}
 
 	// 创建邮箱
-	mailbox, err := h.mailboxes.Create(service.CreateMailboxInput{
+	mailbox, err := h.mailboxes.Create(c.Request.Context(), service.CreateMailboxInput{
 		Prefix:    req.Name,
 		Domain:    req.Domain,
 		IPSource:  c.ClientIP(),
@@ -176,11 +167,16 @@ func (h *CompatHandler) GenerateEmail(c *gin.Context) {
 		ExpiresAt: expiresAt,
 	})
 	if err != nil {
+		if respondQuotaError(c, err) {
+			return
+		}
 		switch err {
 		case service.ErrDomainNotAllowed:
 			c.JSON(http.StatusBadRequest, errorResponse{Error: "invalid domain"})
 		case service.ErrPrefixInvalid:
 			c.JSON(http.StatusBadRequest, errorResponse{Error: "invalid name"})
+		case service.ErrAddressTaken:
+			c.JSON(http.StatusConflict, errorResponse{Error: "address already taken", Reason: ReasonAddressTaken})
 		default:
 			c.JSON(http.StatusInternalServerError, errorResponse{Error: "failed to create mailbox"})
 		}
@@ -235,7 +231,7 @@ func (h *CompatHandler) ListEmails(c *gin.Context) {
 	}
 
 	// 获取邮箱列表
-	mailboxes := h.mailboxes.ListByUserID(userID)
+	mailboxes := h.mailboxes.ListByUserID(c.Request.Context(), userID)
 
 	// 简单分页实现（基于偏移量）
 	offset := 0
@@ -306,8 +302,12 @@ func (h *CompatHandler) ListMessages(c *gin.Context) {
 		limit = 20
 	}
 
+	if h.suspended(c, emailID) {
+		return
+	}
+
 	// 获取邮件列表
-	messages, err := h.messages.List(emailID)
+	messages, err := h.messages.List(c.Request.Context(), emailID)
 	if err != nil {
 		if err == memory.ErrMailboxNotFound {
 			c.JSON(http.StatusNotFound, errorResponse{Error: "mailbox not found"})
@@ -370,9 +370,12 @@ func (h *CompatHandler) ListMessages(c *gin.Context) {
 func (h *CompatHandler) GetMessage(c *gin.Context) {
 	emailID := c.Param("emailId")
 	messageID := c.Param("messageId")
+	if h.suspended(c, emailID) {
+		return
+	}
 
 	// 获取邮件
-	msg, err := h.messages.Get(emailID, messageID)
+	msg, err := h.messages.Get(c.Request.Context(), emailID, messageID)
 	if err != nil {
 		if err == memory.ErrMessageNotFound {
 			c.JSON(http.StatusNotFound, errorResponse{Error: "message not found"})
@@ -383,7 +386,7 @@ func (h *CompatHandler) GetMessage(c *gin.Context) {
 	}
 
 	// 自动标记为已读
-	_ = h.messages.MarkRead(emailID, messageID)
+	_ = h.messages.MarkRead(c.Request.Context(), emailID, messageID)
 
 	// 转换附件信息
 	attachments := make([]attachmentItem, 0, len(msg.Attachments))
@@ -411,3 +414,13 @@ func (h *CompatHandler) GetMessage(c *gin.Context) {
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// suspended 邮箱被停用时返回 403 并中止请求
+func (h *CompatHandler) suspended(c *gin.Context, emailID string) bool {
+	mailbox, err := h.mailboxes.Get(c.Request.Context(), emailID)
+	if err != nil || !mailbox.Suspended {
+		return false
+	}
+	c.JSON(http.StatusForbidden, errorResponse{Error: "mailbox suspended"})
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
 	"tempmail/backend/internal/service"
 )
 
@@ -28,13 +29,13 @@ func NewConfigHandler(configService *service.ConfigService) *ConfigHandler {
 // @Failure 500 {object} Response
 // @Router /v1/admin/config [get]
 func (h *ConfigHandler) GetSystemConfig(c *gin.Context) {
-	config, err := h.configService.GetSystemConfig()
+	config, err := h.configService.GetSystemConfig(c.Request.Context())
 	if err != nil {
 		InternalError(c, "获取系统配置失败")
 		return
 	}
 
-	Success(c, config)
+	Success(c, config.WithoutSecrets())
 }
 
 // UpdateSystemConfigRequest 更新系统配置请求
@@ -43,6 +44,8 @@ type UpdateSystemConfigRequest struct {
 	Mailbox   *domain.MailboxConfig   `json:"mailbox,omitempty"`
 	RateLimit *domain.RateLimitConfig `json:"rateLimit,omitempty"`
 	Security  *domain.SecurityConfig  `json:"security,omitempty"`
+	// 按用户等级的邮件保留时长，如 {"guest": "1h", "tiers": {"free": "1h", "pro": "168h"}}
+	Retention *domain.RetentionPolicyConfig `json:"retention,omitempty"`
 }
 
 // UpdateSystemConfig godoc
@@ -71,16 +74,24 @@ func (h *ConfigHandler) UpdateSystemConfig(c *gin.Context) {
 		Mailbox:   req.Mailbox,
 		RateLimit: req.RateLimit,
 		Security:  req.Security,
+		Retention: req.Retention,
 		UpdatedBy: userID,
 	}
 
-	config, err := h.configService.UpdateSystemConfig(input)
+	var before map[string]any
+	if existing, err := h.configService.GetSystemConfig(c.Request.Context()); err == nil {
+		before = service.AuditSnapshot(existing.WithoutSecrets())
+	}
+
+	config, err := h.configService.UpdateSystemConfig(c.Request.Context(), input)
 	if err != nil {
 		BadRequest(c, err.Error())
 		return
 	}
 
-	SuccessWithMsg(c, "系统配置更新成功", config)
+	h.auditConfig(c, service.AuditConfigUpdate, before, config)
+
+	SuccessWithMsg(c, "系统配置更新成功", config.WithoutSecrets())
 }
 
 // ResetSystemConfig godoc
@@ -95,11 +106,93 @@ func (h *ConfigHandler) UpdateSystemConfig(c *gin.Context) {
 func (h *ConfigHandler) ResetSystemConfig(c *gin.Context) {
 	userID := c.GetString("userID")
 
-	config, err := h.configService.ResetSystemConfig(userID)
+	var before map[string]any
+	if existing, err := h.configService.GetSystemConfig(c.Request.Context()); err == nil {
+		before = service.AuditSnapshot(existing.WithoutSecrets())
+	}
+
+	config, err := h.configService.ResetSystemConfig(c.Request.Context(), userID)
 	if err != nil {
 		InternalError(c, "重置系统配置失败")
 		return
 	}
 
-	SuccessWithMsg(c, "系统配置已重置为默认值", config)
+	h.auditConfig(c, service.AuditConfigReset, before, config)
+
+	SuccessWithMsg(c, "系统配置已重置为默认值", config.WithoutSecrets())
+}
+
+// auditConfig 补充配置变更的审计记录（不含签名密钥；更新时间和更新者每次都会变化，不计入变更）
+func (h *ConfigHandler) auditConfig(c *gin.Context, action string, before map[string]any, config *domain.SystemConfig) {
+	after := service.AuditSnapshot(config.WithoutSecrets())
+	for _, field := range []string{"updatedAt", "updatedBy"} {
+		delete(before, field)
+		delete(after, field)
+	}
+	audit := middleware.AuditEntry(c)
+	audit.Action, audit.TargetType, audit.TargetID = action, domain.AuditTargetConfig, config.ID
+	audit.Changes = service.AuditDiff(before, after)
+}
+
+// SetMaintenanceRequest 切换维护模式请求
+type SetMaintenanceRequest struct {
+	ReadOnly bool   `json:"readOnly"`
+	Message  string `json:"message"`
+}
+
+// GetMaintenance godoc
+// @Summary 获取维护模式状态
+// @Description 获取当前只读维护模式状态（需要管理员权限）
+// @Tags Admin - Config
+// @Produce json
+// @Success 200 {object} Response{data=domain.MaintenanceConfig}
+// @Router /v1/admin/maintenance [get]
+func (h *ConfigHandler) GetMaintenance(c *gin.Context) {
+	Success(c, h.configService.Maintenance())
+}
+
+// SetMaintenance godoc
+// @Summary 切换维护模式
+// @Description 开启或关闭只读维护模式，开启后所有写操作返回 503，SMTP 返回 421（需要超级管理员权限）
+// @Tags Admin - Config
+// @Accept json
+// @Produce json
+// @Param request body SetMaintenanceRequest true "维护模式参数"
+// @Success 200 {object} Response{data=domain.MaintenanceConfig}
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Router /v1/admin/maintenance [post]
+func (h *ConfigHandler) SetMaintenance(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req SetMaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	before := h.configService.Maintenance()
+	maintenance, err := h.configService.SetMaintenance(c.Request.Context(), service.SetMaintenanceInput{
+		ReadOnly:  req.ReadOnly,
+		Message:   req.Message,
+		UpdatedBy: userID,
+	})
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	// 审计记录中间件补充操作者；这里记录开关和说明的变化（切换时间和操作者每次都会变化，不计入变更）
+	after := *maintenance
+	before.UpdatedAt, before.UpdatedBy = nil, ""
+	after.UpdatedAt, after.UpdatedBy = nil, ""
+	audit := middleware.AuditEntry(c)
+	audit.Action, audit.TargetType, audit.TargetID = service.AuditMaintenanceSet, domain.AuditTargetConfig, "maintenance"
+	audit.Changes = service.AuditDiff(before, after)
+
+	msg := "维护模式已关闭"
+	if maintenance.ReadOnly {
+		msg = "维护模式已开启"
+	}
+	SuccessWithMsg(c, msg, maintenance)
 }
@@ -0,0 +1,36 @@
+package httptransport
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/storage/instrumented"
+)
+
+// DebugHandler 运维排查API处理器
+type DebugHandler struct {
+	recorder *instrumented.Recorder
+}
+
+// NewDebugHandler 创建排查处理器
+func NewDebugHandler(recorder *instrumented.Recorder) *DebugHandler {
+	return &DebugHandler{recorder: recorder}
+}
+
+// SlowQueries godoc
+// @Summary 最慢的存储调用
+// @Description 返回进程启动以来最慢的存储方法调用和 SQL（超过慢调用阈值才记录，参数已截断脱敏，SQL 不含参数值）
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=object{thresholdMs=int,items=[]instrumented.SlowCall,count=int}}
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Router /v1/admin/debug/slow-queries [get]
+func (h *DebugHandler) SlowQueries(c *gin.Context) {
+	calls := h.recorder.SlowCalls()
+	Success(c, gin.H{
+		"thresholdMs": h.recorder.Threshold().Milliseconds(),
+		"items":       calls,
+		"count":       len(calls),
+	})
+}
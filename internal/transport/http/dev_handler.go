@@ -0,0 +1,209 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/devmail"
+	"tempmail/backend/internal/middleware"
+)
+
+// MailInjector 将原始邮件经 SMTP 入库流程投递到本实例（由 smtp.Backend 实现）
+type MailInjector interface {
+	Inject(ctx context.Context, from string, to []string, raw []byte) error
+}
+
+// devDefaultSender 开发发信未指定发件人时使用的地址
+const devDefaultSender = "dev@localhost"
+
+// devMaxBatch 单次批量生成的邮件数上限
+const devMaxBatch = 500
+
+// DevHandler 开发模式发信（只在 log.development 开启时注册，无需认证）
+type DevHandler struct {
+	injector MailInjector
+}
+
+// NewDevHandler 创建开发模式发信处理器
+func NewDevHandler(injector MailInjector) *DevHandler {
+	return &DevHandler{injector: injector}
+}
+
+// devAttachment 开发发信附件（内容为 base64）
+type devAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+// devSendRequest 开发发信请求
+type devSendRequest struct {
+	To          string          `json:"to" binding:"required"`
+	From        string          `json:"from"`
+	Subject     string          `json:"subject"`
+	Text        string          `json:"text"`
+	HTML        string          `json:"html"`
+	Attachments []devAttachment `json:"attachments"`
+	Template    string          `json:"template"` // 内置示例邮件模板，设置后忽略主题、正文和附件
+}
+
+// devBatchRequest 批量生成随机邮件请求
+type devBatchRequest struct {
+	To    string `json:"to" binding:"required"`
+	Count int    `json:"count"` // 默认 20，最多 500
+	Seed  *int64 `json:"seed"`  // 随机种子，相同种子生成相同内容
+}
+
+// ListTemplates godoc
+// @Summary 内置示例邮件模板
+// @Description 列出 /v1/dev/send 的 template 参数可选的示例邮件（仅开发模式）
+// @Tags Dev
+// @Produce json
+// @Success 200 {object} Response{data=[]string}
+// @Router /v1/dev/templates [get]
+func (h *DevHandler) ListTemplates(c *gin.Context) {
+	Success(c, devmail.Templates())
+}
+
+// SendMail godoc
+// @Summary 模拟发信
+// @Description 按字段合成 MIME 邮件（或渲染内置示例模板），经与 SMTP 相同的入库流程投递到本实例的邮箱：解析、文件存储、通知、过滤和 Webhook 与真实收信一致（仅开发模式）
+// @Tags Dev
+// @Accept json
+// @Produce json
+// @Param request body devSendRequest true "邮件内容"
+// @Success 201 {object} Response
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/dev/send [post]
+func (h *DevHandler) SendMail(c *gin.Context) {
+	var req devSendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	var (
+		raw []byte
+		err error
+	)
+	from := req.From
+	if req.Template != "" {
+		// 未指定发件人时邮件头使用模板自带的发件人
+		raw, err = devmail.Render(req.Template, from, req.To)
+		if errors.Is(err, devmail.ErrUnknownTemplate) {
+			BadRequest(c, MsgDevUnknownTemplate)
+			return
+		}
+	} else {
+		if from == "" {
+			from = devDefaultSender
+		}
+		draft := devmail.Draft{From: from, To: req.To, Subject: req.Subject, Text: req.Text, HTML: req.HTML}
+		for _, attachment := range req.Attachments {
+			content, decodeErr := base64.StdEncoding.DecodeString(attachment.Content)
+			if decodeErr != nil {
+				BadRequest(c, MsgDevInvalidAttachment)
+				return
+			}
+			draft.Attachments = append(draft.Attachments, devmail.Attachment{
+				Filename:    attachment.Filename,
+				ContentType: attachment.ContentType,
+				Content:     content,
+			})
+		}
+		raw, err = devmail.Build(draft)
+	}
+	if err != nil {
+		InternalError(c, MsgDevBuildFailed)
+		return
+	}
+
+	if from == "" {
+		from = devDefaultSender
+	}
+	if err := h.injector.Inject(c.Request.Context(), from, []string{req.To}, raw); err != nil {
+		h.injectError(c, err)
+		return
+	}
+	Created(c, gin.H{"to": req.To, "size": len(raw)})
+}
+
+// SendBatch godoc
+// @Summary 批量生成随机邮件
+// @Description 向指定邮箱投递 N 封随机邮件（lorem 主题和正文、随机发件人、部分带附件），用于分页和滚动测试（仅开发模式）
+// @Tags Dev
+// @Accept json
+// @Produce json
+// @Param request body devBatchRequest true "目标邮箱和数量"
+// @Success 201 {object} Response
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/dev/send/batch [post]
+func (h *DevHandler) SendBatch(c *gin.Context) {
+	var req devBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 20
+	}
+	if req.Count < 0 || req.Count > devMaxBatch {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	sent := 0
+	for _, draft := range devmail.Generate(rand.New(rand.NewSource(seed)), req.To, req.Count) {
+		raw, err := devmail.Build(draft)
+		if err != nil {
+			InternalError(c, MsgDevBuildFailed)
+			return
+		}
+		if err := h.injector.Inject(c.Request.Context(), draft.From, []string{req.To}, raw); err != nil {
+			h.injectError(c, err)
+			return
+		}
+		sent++
+	}
+	Created(c, gin.H{"to": req.To, "count": sent, "seed": seed})
+}
+
+// devTryLaterRetryAfter SMTP 临时错误（4xx）时建议的重试间隔
+const devTryLaterRetryAfter = 30 * time.Second
+
+// injectError 将 SMTP 入库错误映射为 HTTP 响应
+func (h *DevHandler) injectError(c *gin.Context, err error) {
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) {
+		InternalError(c, MsgDevDeliverFailed)
+		return
+	}
+	switch {
+	case smtpErr.Code == 550 || smtpErr.Code == 501:
+		// 收件人不存在或域名不由本实例管理
+		NotFound(c, MsgDevRecipientNotLocal)
+	case smtpErr.Code >= 500:
+		UnprocessableEntity(c, smtpErr.Message)
+	default:
+		// 4xx：维护模式、评分服务超时等临时错误
+		middleware.RespondThrottled(c, middleware.Throttle{
+			Status:     http.StatusServiceUnavailable,
+			ErrorCode:  middleware.ErrorCodeTryLater,
+			Message:    smtpErr.Message,
+			RetryAfter: devTryLaterRetryAfter,
+		})
+	}
+}
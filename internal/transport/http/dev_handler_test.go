@@ -0,0 +1,134 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// recordingInjector 记录注入的邮件；to 不在 local 中时返回 550
+type recordingInjector struct {
+	mu    sync.Mutex
+	local map[string]bool
+	raws  []string
+	froms []string
+}
+
+func (r *recordingInjector) Inject(_ context.Context, from string, to []string, raw []byte) error {
+	for _, addr := range to {
+		if !r.local[addr] {
+			return &gosmtp.SMTPError{Code: 550, EnhancedCode: gosmtp.EnhancedCode{5, 1, 1}, Message: "recipient mailbox not found"}
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.froms = append(r.froms, from)
+	r.raws = append(r.raws, string(raw))
+	return nil
+}
+
+func TestDevRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(development bool, injector MailInjector) *gin.Engine {
+		store := memory.NewStore(time.Hour)
+		cfg := &config.Config{}
+		cfg.CORS.AllowedOrigins = []string{"*"}
+		cfg.Log.Development = development
+		return NewRouter(RouterDependencies{
+			Config:         cfg,
+			MailboxService: service.NewMailboxService(store, store, cfg),
+			MessageService: service.NewMessageService(store),
+			DevMail:        injector,
+			Store:          store,
+		})
+	}
+	post := func(router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("非开发模式不注册路由", func(t *testing.T) {
+		injector := &recordingInjector{local: map[string]bool{"dev@temp.mail": true}}
+		router := newRouter(false, injector)
+		for _, path := range []string{"/v1/dev/send", "/v1/dev/send/batch"} {
+			w := post(router, path, `{"to":"dev@temp.mail","text":"hi"}`)
+			assert.Equal(t, http.StatusNotFound, w.Code, path)
+		}
+		assert.Empty(t, injector.raws)
+	})
+
+	t.Run("开发模式按字段合成邮件并注入", func(t *testing.T) {
+		injector := &recordingInjector{local: map[string]bool{"dev@temp.mail": true}}
+		router := newRouter(true, injector)
+
+		body := `{"to":"dev@temp.mail","subject":"hello","text":"plain","html":"<p>rich</p>",
+			"attachments":[{"filename":"a.txt","content":"` + base64.StdEncoding.EncodeToString([]byte("payload")) + `"}]}`
+		w := post(router, "/v1/dev/send", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		require.Len(t, injector.raws, 1)
+		assert.Equal(t, devDefaultSender, injector.froms[0])
+		raw := injector.raws[0]
+		assert.Contains(t, raw, "Subject: hello\r\n")
+		assert.Contains(t, raw, "multipart/mixed")
+		assert.Contains(t, raw, "multipart/alternative")
+		assert.Contains(t, raw, `filename=a.txt`)
+
+		w = post(router, "/v1/dev/send", `{"to":"dev@temp.mail","template":"verification"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Contains(t, injector.raws[1], "From: no-reply@accounts.example.com\r\n")
+
+		w = post(router, "/v1/dev/send", `{"to":"dev@temp.mail","template":"nope"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = post(router, "/v1/dev/send", `{"to":"dev@temp.mail","attachments":[{"filename":"a","content":"%%%"}]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("只能投递到本实例的邮箱", func(t *testing.T) {
+		injector := &recordingInjector{local: map[string]bool{}}
+		router := newRouter(true, injector)
+		w := post(router, "/v1/dev/send", `{"to":"someone@gmail.com","text":"hi"}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = post(router, "/v1/dev/send/batch", `{"to":"someone@gmail.com","count":3}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("批量生成指定数量的随机邮件", func(t *testing.T) {
+		injector := &recordingInjector{local: map[string]bool{"dev@temp.mail": true}}
+		router := newRouter(true, injector)
+		w := post(router, "/v1/dev/send/batch", `{"to":"dev@temp.mail","count":25,"seed":3}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var resp struct {
+			Data struct {
+				Count int   `json:"count"`
+				Seed  int64 `json:"seed"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 25, resp.Data.Count)
+		assert.Equal(t, int64(3), resp.Data.Seed)
+		assert.Len(t, injector.raws, 25)
+
+		w = post(router, "/v1/dev/send/batch", `{"to":"dev@temp.mail","count":100000}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
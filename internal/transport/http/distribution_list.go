@@ -0,0 +1,257 @@
+package httptransport
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// DistributionListHandler 分发列表处理器
+type DistributionListHandler struct {
+	service *service.DistributionListService
+}
+
+// NewDistributionListHandler 创建分发列表处理器
+func NewDistributionListHandler(service *service.DistributionListService) *DistributionListHandler {
+	return &DistributionListHandler{
+		service: service,
+	}
+}
+
+// CreateDistributionListRequest 创建分发列表请求
+type CreateDistributionListRequest struct {
+	Name    string   `json:"name" binding:"max=100"`
+	Address string   `json:"address" binding:"required,email"`
+	Members []string `json:"members"`         // 成员邮箱ID（有序），也可以是其他分发列表ID
+	OrgID   *string  `json:"orgId,omitempty"` // 可选：创建为组织列表
+}
+
+// UpdateDistributionListRequest 更新分发列表请求
+type UpdateDistributionListRequest struct {
+	Name     *string  `json:"name" binding:"omitempty,max=100"`
+	Members  []string `json:"members"` // 提供时整体替换成员列表
+	IsActive *bool    `json:"isActive"`
+}
+
+// respondListError 输出分发列表相关错误，返回是否已处理
+func respondListError(c *gin.Context, err error) bool {
+	if respondOrgError(c, err) {
+		return true
+	}
+	switch {
+	case errors.Is(err, service.ErrListNotFound):
+		NotFound(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrListAddressTaken):
+		Conflict(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrListDomainNotOwned),
+		errors.Is(err, service.ErrDomainExpired):
+		Forbidden(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrListAddressInvalid),
+		errors.Is(err, service.ErrListTooLarge),
+		errors.Is(err, service.ErrListMemberInvalid),
+		errors.Is(err, service.ErrListLoop),
+		errors.Is(err, service.ErrDomainNotVerified):
+		BadRequest(c, GetErrorMessage(err))
+	default:
+		return false
+	}
+	return true
+}
+
+// Create godoc
+// @Summary 创建分发列表
+// @Description 在自己控制的域名上创建分发列表，发往列表地址的邮件会复制到每个成员邮箱
+// @Tags Distribution Lists
+// @Accept json
+// @Produce json
+// @Param request body CreateDistributionListRequest true "列表信息"
+// @Success 201 {object} domain.DistributionList
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/distribution-lists [post]
+func (h *DistributionListHandler) Create(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	var req CreateDistributionListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	list, err := h.service.Create(c.Request.Context(), service.CreateDistributionListInput{
+		UserID:  userID,
+		OrgID:   req.OrgID,
+		Name:    req.Name,
+		Address: req.Address,
+		Members: req.Members,
+	})
+	if err != nil {
+		if !respondListError(c, err) {
+			InternalError(c, "创建分发列表失败")
+		}
+		return
+	}
+
+	Created(c, list)
+}
+
+// List godoc
+// @Summary 分发列表
+// @Description 列出个人及所在组织的分发列表
+// @Tags Distribution Lists
+// @Produce json
+// @Success 200 {array} domain.DistributionList
+// @Failure 401 {object} Response
+// @Router /v1/distribution-lists [get]
+func (h *DistributionListHandler) List(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	lists, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		InternalError(c, "获取分发列表失败")
+		return
+	}
+
+	Success(c, lists)
+}
+
+// Get godoc
+// @Summary 分发列表详情
+// @Tags Distribution Lists
+// @Produce json
+// @Param id path string true "列表ID"
+// @Success 200 {object} domain.DistributionList
+// @Failure 404 {object} Response
+// @Router /v1/distribution-lists/{id} [get]
+func (h *DistributionListHandler) Get(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	list, err := h.service.Get(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		if !respondListError(c, err) {
+			InternalError(c, "获取分发列表失败")
+		}
+		return
+	}
+
+	Success(c, list)
+}
+
+// Update godoc
+// @Summary 更新分发列表
+// @Description 修改名称、成员（整体替换）或启用状态
+// @Tags Distribution Lists
+// @Accept json
+// @Produce json
+// @Param id path string true "列表ID"
+// @Param request body UpdateDistributionListRequest true "更新内容"
+// @Success 200 {object} domain.DistributionList
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/distribution-lists/{id} [patch]
+func (h *DistributionListHandler) Update(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	var req UpdateDistributionListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	list, err := h.service.Update(c.Request.Context(), userID, c.Param("id"), service.UpdateDistributionListInput{
+		Name:     req.Name,
+		Members:  req.Members,
+		IsActive: req.IsActive,
+	})
+	if err != nil {
+		if !respondListError(c, err) {
+			InternalError(c, "更新分发列表失败")
+		}
+		return
+	}
+
+	Success(c, list)
+}
+
+// Delete godoc
+// @Summary 删除分发列表
+// @Tags Distribution Lists
+// @Param id path string true "列表ID"
+// @Success 204
+// @Failure 404 {object} Response
+// @Router /v1/distribution-lists/{id} [delete]
+func (h *DistributionListHandler) Delete(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if !respondListError(c, err) {
+			InternalError(c, "删除分发列表失败")
+		}
+		return
+	}
+
+	NoContent(c)
+}
+
+// Deliveries godoc
+// @Summary 分发列表投递报告
+// @Description 最近的投递报告，包含投递成功的成员和失败原因（如超出配额）
+// @Tags Distribution Lists
+// @Produce json
+// @Param id path string true "列表ID"
+// @Param limit query int false "返回数量（默认 50）"
+// @Success 200 {array} domain.DistributionListDelivery
+// @Failure 404 {object} Response
+// @Router /v1/distribution-lists/{id}/deliveries [get]
+func (h *DistributionListHandler) Deliveries(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 && n <= 200 {
+			limit = n
+		}
+	}
+
+	deliveries, err := h.service.Deliveries(c.Request.Context(), userID, c.Param("id"), limit)
+	if err != nil {
+		if !respondListError(c, err) {
+			InternalError(c, "获取投递报告失败")
+		}
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*domain.DistributionListDelivery{}
+	}
+
+	Success(c, deliveries)
+}
@@ -0,0 +1,129 @@
+package httptransport
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+)
+
+// DomainWhitelistHandler 用户域名白名单API处理器
+type DomainWhitelistHandler struct {
+	whitelistService *service.DomainWhitelistService
+}
+
+// NewDomainWhitelistHandler 创建域名白名单处理器
+func NewDomainWhitelistHandler(whitelistService *service.DomainWhitelistService) *DomainWhitelistHandler {
+	return &DomainWhitelistHandler{
+		whitelistService: whitelistService,
+	}
+}
+
+// addWhitelistEntryRequest 添加白名单条目请求
+type addWhitelistEntryRequest struct {
+	LocalPart string `json:"localPart" binding:"required"`
+}
+
+// ListWhitelist godoc
+// @Summary 获取用户域名白名单
+// @Description 白名单模式的域名只有列表中的前缀可以创建邮箱和接收邮件
+// @Tags User Domains
+// @Produce json
+// @Param id path string true "域名ID"
+// @Success 200 {object} Response{data=[]domain.DomainWhitelistEntry}
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/user/domains/{id}/whitelist [get]
+func (h *DomainWhitelistHandler) ListWhitelist(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	entries, err := h.whitelistService.List(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	Success(c, entries)
+}
+
+// AddWhitelistEntry godoc
+// @Summary 添加白名单前缀
+// @Description 前缀统一转为小写，可以在切换到白名单模式前预先添加
+// @Tags User Domains
+// @Accept json
+// @Produce json
+// @Param id path string true "域名ID"
+// @Param request body addWhitelistEntryRequest true "邮箱前缀"
+// @Success 201 {object} Response{data=domain.DomainWhitelistEntry}
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/user/domains/{id}/whitelist [post]
+func (h *DomainWhitelistHandler) AddWhitelistEntry(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	var req addWhitelistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	entry, err := h.whitelistService.Add(c.Request.Context(), c.Param("id"), userID, req.LocalPart)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	Created(c, entry)
+}
+
+// DeleteWhitelistEntry godoc
+// @Summary 删除白名单前缀
+// @Description 已创建的邮箱保留，但域名处于白名单模式时不再接收邮件
+// @Tags User Domains
+// @Param id path string true "域名ID"
+// @Param entryId path string true "条目ID"
+// @Success 204
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/user/domains/{id}/whitelist/{entryId} [delete]
+func (h *DomainWhitelistHandler) DeleteWhitelistEntry(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	if err := h.whitelistService.Remove(c.Request.Context(), c.Param("id"), userID, c.Param("entryId")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	NoContent(c)
+}
+
+// respondError 将白名单服务错误映射为响应
+func (h *DomainWhitelistHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrDomainNotFound), errors.Is(err, service.ErrWhitelistEntryNotFound):
+		NotFound(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrNotDomainOwner):
+		Forbidden(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrWhitelistLocalPartInvalid), errors.Is(err, service.ErrWhitelistFull):
+		BadRequest(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrWhitelistEntryExists):
+		Conflict(c, GetErrorMessage(err))
+	default:
+		InternalError(c, MsgWhitelistUpdateFailed)
+	}
+}
@@ -1,6 +1,11 @@
 package httptransport
 
 import (
+	"tempmail/backend/internal/analytics"
+	"tempmail/backend/internal/jobs"
+	"tempmail/backend/internal/mailflow"
+	"tempmail/backend/internal/redact"
+	"tempmail/backend/internal/security"
 	"tempmail/backend/internal/service"
 	"tempmail/backend/internal/storage/memory"
 )
@@ -8,19 +13,41 @@ import (
 // 错误消息映射表（业务错误 -> 中文消息）
 var errorMessages = map[error]string{
 	// Mailbox 错误
-	service.ErrDomainNotAllowed: "域名不在允许列表中",
-	service.ErrPrefixInvalid:    "邮箱前缀格式无效",
-	memory.ErrMailboxNotFound:   "邮箱不存在",
+	service.ErrDomainNotAllowed:     "域名不在允许列表中",
+	service.ErrPrefixInvalid:        "邮箱前缀格式无效",
+	service.ErrInvalidMailboxSort:   "排序方式无效（可选 lastActivity、createdAt、address）",
+	service.ErrBatchCountInvalid:    "批量创建数量无效（1~500）",
+	service.ErrBatchPatternInvalid:  "前缀模板无效（必须包含一次 {rand}）",
+	service.ErrInvalidMessageSort:   "排序方式无效（sort 可选 receivedAt、subject、from，order 可选 desc、asc）",
+	service.ErrInvalidMessagePage:   "分页参数无效（limit、offset 须为非负整数）",
+	service.ErrAddressTaken:         "该地址已被占用",
+	service.ErrMailboxQuotaExceeded: "邮箱数量已达账户等级上限",
+	memory.ErrMailboxNotFound:       "邮箱不存在",
 
 	// Message 错误
 	memory.ErrMessageNotFound: "邮件不存在",
 
+	// 翻译错误
+	service.ErrTranslationNotConfigured: "未配置翻译服务",
+	service.ErrInvalidTargetLanguage:    "目标语言无效，请使用 ISO 639-1 代码（如 en）",
+	service.ErrNothingToTranslate:       "邮件没有可翻译的正文",
+
 	// User Domain 错误
 	service.ErrInvalidDomain:       "域名格式无效",
 	service.ErrDomainAlreadyExists: "域名已存在",
 	service.ErrDomainNotFound:      "域名不存在",
 	service.ErrNotDomainOwner:      "您不是该域名的所有者",
 	service.ErrDomainVerifyFailed:  "域名验证失败，请检查DNS记录",
+	service.ErrDomainIsSystem:      "该域名为系统域名，无法添加",
+	service.ErrDomainNotVerified:   "域名尚未验证",
+	service.ErrDomainExpired:       "域名已过期，请续期后再创建邮箱",
+	service.ErrInvalidExpiry:       "到期时间必须晚于当前时间",
+	service.ErrMailboxFrozen:       "域名已过期，邮箱为只读",
+
+	// System Domain 错误
+	service.ErrSystemDomainOwnedByUser: "该域名已被其他用户验证使用",
+	service.ErrUserDomainNotFound:      "用户域名不存在",
+	service.ErrInvalidClaimStrategy:    "无效的邮箱处理策略",
 
 	// Admin 错误
 	service.ErrAdminUserNotFound:      "用户不存在",
@@ -31,6 +58,206 @@ var errorMessages = map[error]string{
 	// API Key 错误
 	service.ErrAPIKeyNotFound: "API Key不存在",
 	service.ErrAPIKeyInvalid:  "API Key无效",
+
+	// 统计错误
+	service.ErrInvalidStatsWindow:  "统计窗口格式无效，请使用 7d 或 36h 形式",
+	service.ErrStatsWindowTooLarge: "统计窗口不能超过 90 天",
+
+	// 组织错误
+	service.ErrOrgNameRequired:      "组织名称不能为空",
+	service.ErrOrgNotFound:          "组织不存在",
+	service.ErrNotOrgMember:         "您不是该组织的成员",
+	service.ErrOrgOwnerRequired:     "需要组织所有者权限",
+	service.ErrCannotRemoveOrgOwner: "不能移除组织所有者",
+	service.ErrInviteInvalid:        "邀请无效或已过期",
+	service.ErrInviteEmailMismatch:  "该邀请不是发给当前账号的",
+	service.ErrOrgQuotaExceeded:     "组织邮箱数量已达上限",
+
+	// 分发列表错误
+	service.ErrListNotFound:       "分发列表不存在",
+	service.ErrListAddressInvalid: "分发列表地址无效",
+	service.ErrListAddressTaken:   "该地址已被邮箱、别名或其他分发列表使用",
+	service.ErrListDomainNotOwned: "分发列表地址必须位于您控制的域名",
+	service.ErrListTooLarge:       "分发列表成员数量超出上限",
+	service.ErrListMemberInvalid:  "成员必须是同一所有者的邮箱或分发列表",
+	service.ErrListLoop:           "分发列表不能包含自身（循环引用）",
+	service.ErrMailboxFull:        "邮箱邮件数量已达上限",
+
+	// 垃圾邮件阈值错误
+	service.ErrSpamThresholdInvalid: "垃圾邮件阈值超出允许范围（需大于 0、不超过系统上限，且软阈值低于硬阈值）",
+
+	// 邮箱续期错误
+	service.ErrRenewRequiresAccount:    "仅注册用户的邮箱可以续期或开启自动续期",
+	service.ErrExtendDurationInvalid:   "续期时长必须为正数",
+	service.ErrMailboxLifetimeExceeded: "超出用户等级允许的邮箱最长生存时间",
+
+	// 认领游客邮箱错误
+	service.ErrClaimTokenInvalid:   "邮箱令牌无效",
+	service.ErrMailboxAlreadyOwned: "该邮箱已属于其他账户",
+	service.ErrClaimPublicInbox:    "公开收件箱不能认领",
+	service.ErrMailboxSuspended:    "邮箱已被停用",
+
+	// 原始邮件错误
+	service.ErrMessageRawUnavailable: "该邮件没有保存原始内容",
+
+	// HTML 渲染和图片代理错误
+	service.ErrMessageHTMLUnavailable: "该邮件没有 HTML 正文",
+	service.ErrImageProxySignature:    "图片代理地址无效",
+	service.ErrImageProxyFetch:        "加载远程图片失败",
+	service.ErrImageProxyType:         "远程资源不是支持的图片格式",
+	service.ErrImageProxyTooLarge:     "远程图片过大",
+
+	// 验证码提取错误
+	service.ErrOTPNotFound: "没有找到包含验证码或验证链接的邮件",
+
+	// 附件病毒扫描错误
+	service.ErrAttachmentInfected: "附件检出病毒，已禁止下载",
+
+	// 全文搜索错误
+	service.ErrSearchIndexUnavailable: "全文搜索未启用",
+	service.ErrSearchQueryRequired:    "请提供搜索关键词",
+
+	// 邮件到期规则错误
+	service.ErrTooManyExpiryRules: "到期规则数量超出上限（最多 10 条）",
+	service.ErrExpiryRulePattern:  "到期规则中的正则无效",
+	service.ErrExpiryRuleTTL:      "到期时长须在 1 分钟到邮箱有效期之间",
+
+	// 脱敏错误
+	redact.ErrNoRules:          "请至少指定一条脱敏规则或自定义正则",
+	redact.ErrUnknownRule:      "未知的脱敏规则（可用：emails、phones、token_urls、codes）",
+	redact.ErrInvalidPattern:   "自定义正则无效",
+	redact.ErrTooManyPatterns:  "自定义正则数量超出上限",
+	service.ErrNothingToRedact: "邮件没有可脱敏的内容",
+
+	// 黑洞模式错误
+	service.ErrSinkRequiresExclusive:  "黑洞模式仅适用于已验证的独享模式域名",
+	service.ErrInvalidSinkSampleRate:  "抽样间隔无效（0 表示不抽样，最大 1000000）",
+	service.ErrSinkDiagnosticsMailbox: "诊断邮箱不存在或无权使用（开启抽样时必须指定）",
+
+	// 通配收件错误
+	service.ErrCatchAllRequiresVerified: "通配收件仅适用于已验证的独享或通配模式域名",
+	service.ErrCatchAllMailbox:          "通配邮箱不存在或无权使用",
+
+	// 域名白名单错误
+	service.ErrWhitelistLocalPartInvalid: "白名单前缀无效",
+	service.ErrWhitelistEntryExists:      "该前缀已在白名单中",
+	service.ErrWhitelistEntryNotFound:    "白名单条目不存在",
+	service.ErrWhitelistFull:             "白名单条目数量已达上限（最多 500 条）",
+	service.ErrAddressNotWhitelisted:     "该域名为白名单模式，只能使用白名单中的前缀创建邮箱",
+
+	// 保留前缀错误
+	service.ErrPrefixReserved:           "该前缀已被保留，不能使用",
+	service.ErrReservedPrefixInvalid:    "保留前缀无效",
+	service.ErrReservedPrefixExists:     "该前缀已被保留",
+	service.ErrReservedPrefixNotFound:   "保留前缀不存在",
+	service.ErrReservedDomainInvalid:    "只能在共享的系统域名上保留前缀",
+	service.ErrVanityRequiresPaidTier:   "预留靓号前缀需要付费套餐",
+	service.ErrVanityLimitReached:       "靓号前缀数量已达上限（最多 5 个）",
+	service.ErrReservationPeriodInvalid: "保留期限无效（最长 365 天）",
+
+	// 配置备份错误
+	service.ErrRestoreConflicts: "配置恢复存在冲突，请先预演并处理冲突项",
+
+	// 公开收件箱错误
+	service.ErrPublicInboxNotAllowed:    "该域名不允许创建公开收件箱",
+	service.ErrPublicInboxNotFound:      "公开收件箱不存在",
+	service.ErrPublicMessageNotFound:    "邮件不存在或已过期",
+	service.ErrPublicAttachmentTooLarge: "附件超出公开下载大小上限",
+
+	// 邮箱 Webhook 错误
+	service.ErrWebhookNotFound:     "Webhook 不存在",
+	service.ErrMailboxWebhookLimit: "该邮箱的 Webhook 数量已达上限",
+	service.ErrMailboxWebhookEvent: "邮箱 Webhook 只支持 mail.received、mailbox.expired、message.deleted 和 quota.exceeded 事件",
+
+	// Webhook 回调内容错误
+	service.ErrWebhookPayloadFormat:   "不支持的回调内容格式（headers、full 或 custom）",
+	service.ErrWebhookTemplateInvalid: "回调模板无效",
+	service.ErrWebhookTestEvent:       "不支持的测试事件类型",
+	service.ErrWebhookEventInvalid:    "不支持的事件类型",
+	security.ErrUnsafeURL:             "回调地址必须是公网 HTTP(S) 地址",
+
+	// Webhook 重新投递错误
+	service.ErrWebhookDeliveryNotFound:  "投递记录不存在",
+	service.ErrWebhookDeliveryNoPayload: "该投递记录没有可重新投递的回调内容",
+
+	// 滥用举报错误
+	service.ErrAbuseTargetInvalid:    "举报对象必须是邮箱地址或邮件链接",
+	service.ErrAbuseCategoryInvalid:  "举报类别无效（spam、phishing、illegal 或 other）",
+	service.ErrAbuseDetailsInvalid:   "请填写举报说明（最多 2000 字）",
+	service.ErrAbuseReporterInvalid:  "举报人邮箱格式无效",
+	service.ErrAbuseReportNotFound:   "举报不存在",
+	service.ErrAbuseReportTransition: "举报已处理，不能再变更状态",
+	service.ErrAbuseActionInvalid:    "处置方式无效（quarantine、suspend 或 dismiss）",
+	service.ErrAbuseReportUnlinked:   "举报未关联到邮箱或邮件，只能驳回",
+
+	// 邮件分享链接错误
+	service.ErrShareExpiryInvalid:         "分享有效期必须在 1 分钟到 7 天之间",
+	service.ErrShareMessageNotFound:       "邮件不存在",
+	service.ErrShareNotAllowed:            "隔离区中的邮件不能分享",
+	service.ErrShareRedactionMissing:      "请先生成脱敏副本再分享",
+	service.ErrShareNotFound:              "分享链接不存在",
+	service.ErrShareLinkInvalid:           "分享链接无效",
+	service.ErrShareLinkExpired:           "分享链接已过期或已被撤销",
+	service.ErrShareAttachmentsNotAllowed: "该分享链接不允许下载附件",
+	service.ErrShareMailboxNotFound:       "邮箱不存在",
+
+	// 发信（回复）错误
+	service.ErrSendingDisabled:       "未配置发信中继",
+	service.ErrSendRequiresAccount:   "游客邮箱不能发信，请登录后使用",
+	service.ErrSendRecipientsInvalid: "收件人为空或地址无效",
+	service.ErrSendTooManyRecipients: "收件人数量超过上限",
+	service.ErrSendBodyEmpty:         "邮件正文不能为空",
+	service.ErrSendBodyTooLarge:      "邮件正文过大（最大 1MB）",
+	service.ErrSendReplyNotFound:     "要回复的邮件不存在",
+	service.ErrSendRelayFailed:       "发信中继投递失败，请稍后重试",
+
+	// 邮箱转发错误
+	service.ErrForwardRequiresAccount: "游客邮箱不能设置转发，请登录后使用",
+	service.ErrForwardAddressInvalid:  "转发地址无效",
+	service.ErrForwardAddressLocal:    "不能转发到本站的域名",
+	service.ErrForwardLimit:           "转发地址数量已达上限",
+	service.ErrForwardNotFound:        "转发地址不存在",
+	service.ErrForwardVerified:        "该转发地址已确认",
+	service.ErrForwardCodeInvalid:     "确认码错误",
+	service.ErrForwardCodeExpired:     "确认码已过期或尝试次数过多，请重新添加该地址获取新的确认码",
+
+	// 账户自助管理错误
+	service.ErrEmailVerificationUnavailable: "未配置发信中继，暂不能验证邮箱",
+	service.ErrEmailAlreadyVerified:         "邮箱已验证",
+	service.ErrEmailCodeCooldown:            "验证码发送过于频繁，请稍后再试",
+	service.ErrEmailCodeInvalid:             "验证码错误",
+	service.ErrEmailCodeExpired:             "验证码已过期或尝试次数过多，请重新发送",
+	service.ErrAccountConfirmMismatch:       "确认信息与账户邮箱不一致",
+	service.ErrAccountIsSuper:               "超级管理员账户不能注销",
+	service.ErrAccountOwnsOrganization:      "请先转让或删除您创建的组织再注销账户",
+
+	// 审计与代登录错误
+	service.ErrAuditQueryInvalid:           "审计记录查询条件无效",
+	service.ErrImpersonateSelf:             "不能代登录自己的账户",
+	service.ErrImpersonateAdmin:            "不能代登录管理员账户",
+	service.ErrImpersonateInactive:         "不能代登录已禁用的账户",
+	service.ErrImpersonationTTLInvalid:     "代登录有效期必须在 1 分钟到 1 小时之间",
+	service.ErrImpersonationReasonRequired: "请填写代登录原因（最多 500 字）",
+
+	// 邮箱导出错误
+	service.ErrExportFormatInvalid: "导出格式无效（mbox 或 eml-zip）",
+
+	// 维护任务错误
+	jobs.ErrUnknownJobType: "任务类型无效（backfill-previews 或 recount-mailboxes）",
+	jobs.ErrJobActive:      "同类型的任务正在排队或运行",
+	jobs.ErrJobNotFound:    "任务不存在",
+	jobs.ErrJobFinished:    "任务已结束，不能取消",
+
+	// 使用情况统计错误
+	analytics.ErrUnknownMetric:    "统计指标无效",
+	analytics.ErrInvalidInterval:  "统计粒度无效（hour 或 day）",
+	analytics.ErrInvalidTimeRange: "统计时间范围无效",
+	analytics.ErrTimeRangeTooLong: "统计时间范围过长（按小时最多 31 天，按天最多 400 天）",
+
+	// 收信流量看板错误
+	mailflow.ErrInvalidInterval:  "流量统计粒度无效（minute 或 hour）",
+	mailflow.ErrInvalidTimeRange: "流量统计时间范围无效",
+	mailflow.ErrTimeRangeTooLong: "流量统计时间范围过长（按分钟最多 24 小时，按小时最多 7 天）",
 }
 
 // GetErrorMessage 获取错误的中文消息
@@ -44,11 +271,13 @@ func GetErrorMessage(err error) string {
 // 通用错误消息
 const (
 	// 请求相关
-	MsgInvalidRequest   = "请求参数格式错误"
-	MsgInvalidJSON      = "JSON格式错误"
-	MsgInvalidExpiresIn = "过期时间格式无效"
-	MsgInvalidDuration  = "时长格式无效"
-	MsgRequestBodyEmpty = "请求体不能为空"
+	MsgInvalidRequest      = "请求参数格式错误"
+	MsgInvalidJSON         = "JSON格式错误"
+	MsgInvalidExpiresIn    = "过期时间格式无效"
+	MsgInvalidDuration     = "时长格式无效"
+	MsgRequestBodyEmpty    = "请求体不能为空"
+	MsgInvalidHeaderFilter = "头过滤条件无效（header.<名称>=<值>，名称只能包含字母、数字、连字符和下划线）"
+	MsgInvalidDKIMResult   = "DKIM 验证结果无效（pass、fail 或 none）"
 
 	// 认证相关
 	MsgAuthRequired       = "需要登录认证"
@@ -57,17 +286,48 @@ const (
 	MsgTokenInvalid       = "无效的访问令牌"
 	MsgPermissionDenied   = "权限不足"
 
+	MsgAccountLocked        = "登录失败次数过多，账户已临时锁定"
+	MsgTooManyLoginAttempts = "登录尝试过于频繁，请稍后再试"
+	MsgLoginLockQueryFailed = "获取登录锁定状态失败"
+	MsgLoginLockClearFailed = "解除登录锁定失败"
+
+	MsgOAuthProviderNotFound = "未配置该第三方登录方式"
+	MsgOAuthStateInvalid     = "登录请求已失效，请重新发起第三方登录"
+	MsgOAuthFailed           = "第三方登录失败，请稍后重试"
+	MsgOAuthEmailUnverified  = "第三方账号没有已验证的邮箱，无法登录"
+
+	MsgTwoFactorUnavailable      = "两步验证暂不可用"
+	MsgTwoFactorEnabled          = "已启用两步验证"
+	MsgTwoFactorNotEnabled       = "未启用两步验证"
+	MsgTwoFactorNotSetup         = "请先生成两步验证密钥"
+	MsgTwoFactorCodeInvalid      = "验证码无效"
+	MsgTwoFactorChallengeInvalid = "两步验证已过期，请重新登录"
+	MsgTwoFactorFailed           = "两步验证操作失败"
+
+	MsgRefreshTokenInvalid = "刷新令牌无效"
+	MsgRefreshTokenReused  = "刷新令牌已被使用，该会话已注销，请重新登录"
+	MsgSessionNotFound     = "会话不存在"
+	MsgSessionFailed       = "会话操作失败"
+
+	MsgIncorrectPassword   = "当前密码错误"
+	MsgUsernameExists      = "该用户名已被占用"
+	MsgAccountUpdateFailed = "账户操作失败"
+
 	// 邮箱相关
 	MsgMailboxCreateFailed = "创建邮箱失败"
 	MsgMailboxNotFound     = "邮箱不存在"
 	MsgMailboxDeleteFailed = "删除邮箱失败"
 
 	// 邮件相关
-	MsgMessageCreateFailed   = "保存邮件失败"
-	MsgMessageNotFound       = "邮件不存在"
-	MsgMessageListFailed     = "获取邮件列表失败"
-	MsgMessageMarkReadFailed = "标记已读失败"
-	MsgMessageGetFailed      = "获取邮件详情失败"
+	MsgMessageCreateFailed    = "保存邮件失败"
+	MsgMessageNotFound        = "邮件不存在"
+	MsgMessageListFailed      = "获取邮件列表失败"
+	MsgMessageMarkReadFailed  = "标记已读失败"
+	MsgMessageDeleteFailed    = "删除邮件失败"
+	MsgMessageGetFailed       = "获取邮件详情失败"
+	MsgMessageTranslateFailed = "翻译邮件失败"
+	MsgMessageRedactFailed    = "生成脱敏副本失败"
+	MsgRedactionNotFound      = "脱敏副本不存在，请先生成"
 
 	// 附件相关
 	MsgAttachmentNotFound = "附件不存在"
@@ -79,6 +339,11 @@ const (
 	MsgAliasDeleteFailed = "删除别名失败"
 	MsgAliasToggleFailed = "切换别名状态失败"
 
+	// 邮箱 Webhook 相关
+	MsgMailboxWebhookCreateFailed = "创建 Webhook 失败"
+	MsgMailboxWebhookListFailed   = "获取 Webhook 列表失败"
+	MsgMailboxWebhookDeleteFailed = "删除 Webhook 失败"
+
 	// 用户域名相关
 	MsgDomainAddFailed          = "添加域名失败"
 	MsgDomainListFailed         = "获取域名列表失败"
@@ -112,6 +377,78 @@ const (
 	MsgAPIKeyGetFailed    = "获取API Key详情失败"
 	MsgAPIKeyDeleteFailed = "删除API Key失败"
 
+	// 统计相关
+	MsgStatsGetFailed = "获取收件统计失败"
+
+	// 签名密钥相关
+	MsgJWTRotateFailed = "轮换签名密钥失败"
+
+	// 黑洞模式相关
+	MsgSinkUpdateFailed   = "更新黑洞模式失败"
+	MsgSinkStatsGetFailed = "获取黑洞统计失败"
+
+	// 通配收件相关
+	MsgCatchAllUpdateFailed = "更新通配收件设置失败"
+
+	// 域名白名单相关
+	MsgWhitelistUpdateFailed = "更新域名白名单失败"
+
+	// 保留前缀相关
+	MsgReservedPrefixUpdateFailed = "更新保留前缀失败"
+
+	// 配置备份相关
+	MsgBackupExportFailed  = "导出配置失败"
+	MsgBackupRestoreFailed = "恢复配置失败"
+
+	// 个人数据相关
+	MsgDataExportFailed      = "导出个人数据失败"
+	MsgDataExportRateLimited = "每小时只能导出一次个人数据，请稍后再试"
+	MsgRetentionReportFailed = "生成数据保留报告失败"
+
+	// SMTP 会话相关
+	MsgSMTPSessionNotFound = "SMTP 会话不存在或已结束"
+
+	// 公开收件箱相关
+	MsgPublicInboxListFailed   = "获取公开收件箱失败"
+	MsgPublicInboxUpdateFailed = "更新公开状态失败"
+
+	// 滥用举报相关
+	MsgAbuseReportSubmitFailed = "提交举报失败"
+	MsgAbuseReportListFailed   = "获取举报列表失败"
+	MsgAbuseReportUpdateFailed = "处理举报失败"
+
+	// 邮件分享链接相关
+	MsgMessageShareFailed = "创建分享链接失败"
+
+	// 审计与代登录相关
+	MsgAuditLogListFailed = "获取审计记录失败"
+	MsgImpersonateFailed  = "签发代登录令牌失败"
+
+	// 发信相关
+	MsgSendMessageFailed = "发送邮件失败"
+	MsgSendQuotaMailbox  = "该邮箱今日发信数量已达上限"
+	MsgSendQuotaUser     = "账户今日发信数量已达上限"
+
+	// 邮箱转发相关
+	MsgForwardListFailed   = "获取转发地址失败"
+	MsgForwardUpdateFailed = "设置转发地址失败"
+
+	// 维护任务相关
+	MsgMaintenanceJobFailed = "操作维护任务失败"
+
+	// 使用情况统计相关
+	MsgAnalyticsQueryFailed = "查询使用情况统计失败"
+
+	// 收信流量看板相关
+	MsgMailFlowQueryFailed = "查询收信流量失败"
+
+	// 开发模式相关
+	MsgDevRecipientNotLocal = "收件人不是本实例的邮箱"
+	MsgDevUnknownTemplate   = "示例邮件模板不存在"
+	MsgDevInvalidAttachment = "附件内容必须是 base64 编码"
+	MsgDevBuildFailed       = "合成邮件失败"
+	MsgDevDeliverFailed     = "投递邮件失败"
+
 	// 服务器错误
 	MsgInternalError = "服务器内部错误，请稍后重试"
 )
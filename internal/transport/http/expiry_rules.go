@@ -0,0 +1,131 @@
+package httptransport
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage"
+)
+
+// expiryRuleBody 邮件到期规则（条件为空表示不限，按顺序匹配，第一条命中的生效）
+type expiryRuleBody struct {
+	FromPattern    string `json:"fromPattern,omitempty"`    // 发件人正则（不区分大小写）
+	SubjectPattern string `json:"subjectPattern,omitempty"` // 主题正则（不区分大小写）
+	HasAttachment  *bool  `json:"hasAttachment,omitempty"`  // 是否带附件
+	TTL            string `json:"ttl"`                      // 入库后多久删除，如 "15m"、"24h"
+}
+
+// updateExpiryRulesRequest 替换邮箱的到期规则（空列表表示清除）
+type updateExpiryRulesRequest struct {
+	Rules []expiryRuleBody `json:"rules"`
+}
+
+// expiryRulesResponse 邮箱的到期规则
+type expiryRulesResponse struct {
+	Rules     []expiryRuleBody `json:"rules"`
+	Reapplied int              `json:"reapplied"` // reapply=true 时删除时间发生变化的邮件数
+}
+
+func toExpiryRulesResponse(rules []domain.ExpiryRule, reapplied int) expiryRulesResponse {
+	items := make([]expiryRuleBody, 0, len(rules))
+	for _, rule := range rules {
+		items = append(items, expiryRuleBody{
+			FromPattern:    rule.FromPattern,
+			SubjectPattern: rule.SubjectPattern,
+			HasAttachment:  rule.HasAttachment,
+			TTL:            rule.TTL.String(),
+		})
+	}
+	return expiryRulesResponse{Rules: items, Reapplied: reapplied}
+}
+
+// getExpiryRules godoc
+// @Summary 获取邮件到期规则
+// @Description 返回邮箱的邮件到期规则，按顺序匹配
+// @Tags Mailboxes
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Success 200 {object} expiryRulesResponse
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /v1/mailboxes/{id}/expiry-rules [get]
+func (h *Handler) getExpiryRules(c *gin.Context) {
+	rules, err := h.expiry.Rules(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrMailboxNotFound) {
+			NotFound(c, MsgMailboxNotFound)
+			return
+		}
+		InternalError(c, MsgInternalError)
+		return
+	}
+
+	Success(c, toExpiryRulesResponse(rules, 0))
+}
+
+// updateExpiryRules godoc
+// @Summary 设置邮件到期规则
+// @Description 替换邮箱的邮件到期规则（最多 10 条，时长在 1 分钟到邮箱有效期之间）。入库时第一条命中的规则决定邮件的删除时间，如验证码邮件 15 分钟后删除。修改规则不影响已入库的邮件，reapply=true 时按新规则重新计算
+// @Tags Mailboxes
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param reapply query bool false "按新规则重新计算已入库邮件的删除时间"
+// @Param request body updateExpiryRulesRequest true "到期规则"
+// @Success 200 {object} expiryRulesResponse
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /v1/mailboxes/{id}/expiry-rules [put]
+func (h *Handler) updateExpiryRules(c *gin.Context) {
+	var req updateExpiryRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	reapply := false
+	if raw := c.Query("reapply"); raw != "" {
+		var err error
+		if reapply, err = strconv.ParseBool(raw); err != nil {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+	}
+
+	rules := make([]domain.ExpiryRule, 0, len(req.Rules))
+	for _, item := range req.Rules {
+		ttl, err := time.ParseDuration(item.TTL)
+		if err != nil {
+			BadRequest(c, MsgInvalidDuration)
+			return
+		}
+		rules = append(rules, domain.ExpiryRule{
+			FromPattern:    item.FromPattern,
+			SubjectPattern: item.SubjectPattern,
+			HasAttachment:  item.HasAttachment,
+			TTL:            ttl,
+		})
+	}
+
+	mailbox, reapplied, err := h.expiry.UpdateRules(c.Request.Context(), c.Param("id"), rules, reapply)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTooManyExpiryRules),
+			errors.Is(err, service.ErrExpiryRuleTTL),
+			errors.Is(err, service.ErrExpiryRulePattern):
+			BadRequest(c, GetErrorMessage(err))
+		case errors.Is(err, storage.ErrMailboxNotFound):
+			NotFound(c, MsgMailboxNotFound)
+		default:
+			InternalError(c, MsgInternalError)
+		}
+		return
+	}
+
+	Success(c, toExpiryRulesResponse(mailbox.ExpiryRules, reapplied))
+}
@@ -0,0 +1,95 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestExpiryRuleRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-a", Address: "a@temp.mail", LocalPart: "a", Domain: "temp.mail", Token: "tok-a", CreatedAt: time.Now(),
+	}))
+	cfg := &config.Config{Mailbox: config.MailboxConfig{DefaultTTL: 24 * time.Hour}}
+	cfg.CORS.AllowedOrigins = []string{"*"}
+	messages := service.NewMessageService(store)
+	router := NewRouter(RouterDependencies{
+		Config:               cfg,
+		MailboxService:       service.NewMailboxService(store, store, cfg),
+		MessageService:       messages,
+		MessageExpiryService: service.NewMessageExpiryService(store, messages, cfg),
+		Store:                store,
+	})
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("X-Mailbox-Token", token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("需要邮箱Token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/v1/mailboxes/mb-a/expiry-rules", "", "").Code)
+	})
+
+	t.Run("无效规则返回 400", func(t *testing.T) {
+		for _, body := range []string{
+			`{"rules":[{"subjectPattern":"code","ttl":"soon"}]}`,
+			`{"rules":[{"subjectPattern":"code","ttl":"30s"}]}`,
+			`{"rules":[{"subjectPattern":"code","ttl":"48h"}]}`,
+			`{"rules":[{"subjectPattern":"(","ttl":"15m"}]}`,
+		} {
+			w := do(http.MethodPut, "/v1/mailboxes/mb-a/expiry-rules", "tok-a", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+		w := do(http.MethodPut, "/v1/mailboxes/mb-a/expiry-rules?reapply=maybe", "tok-a", `{"rules":[]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("设置规则后新邮件返回 expiresAt", func(t *testing.T) {
+		w := do(http.MethodPut, "/v1/mailboxes/mb-a/expiry-rules", "tok-a", `{"rules":[{"subjectPattern":"verification","ttl":"15m"}]}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = do(http.MethodGet, "/v1/mailboxes/mb-a/expiry-rules", "tok-a", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var rules struct {
+			Data expiryRulesResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rules))
+		require.Len(t, rules.Data.Rules, 1)
+		assert.Equal(t, "verification", rules.Data.Rules[0].SubjectPattern)
+		assert.Equal(t, "15m0s", rules.Data.Rules[0].TTL)
+
+		w = do(http.MethodPost, "/v1/mailboxes/mb-a/messages", "tok-a", `{"from":"no-reply@example.com","subject":"Your verification code","text":"123456"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created struct {
+			Data messageResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		require.NotNil(t, created.Data.ExpiresAt)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), *created.Data.ExpiresAt, time.Minute)
+
+		w = do(http.MethodPost, "/v1/mailboxes/mb-a/messages", "tok-a", `{"from":"friend@example.com","subject":"Hello","text":"hi"}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.NotContains(t, w.Body.String(), "expiresAt")
+	})
+}
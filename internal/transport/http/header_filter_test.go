@@ -0,0 +1,81 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestHeaderFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "ci@temp.mail", LocalPart: "ci", Domain: "temp.mail", CreatedAt: time.Now(),
+	}))
+	messages := service.NewMessageService(store)
+	for _, run := range []string{"4711", "4712"} {
+		_, err := messages.Create(t.Context(), service.CreateMessageInput{
+			MailboxID: "mb-1", From: "ci@example.com", Subject: "run " + run,
+			CapturedHeaders: map[string]string{"X-Test-Run-Id": run},
+		})
+		require.NoError(t, err)
+	}
+	_, err := messages.Create(t.Context(), service.CreateMessageInput{MailboxID: "mb-1", From: "ci@example.com", Subject: "untagged"})
+	require.NoError(t, err)
+
+	h := &Handler{messages: messages, search: service.NewSearchService(store)}
+	router := gin.New()
+	router.GET("/v1/mailboxes/:id/messages", h.listMessages)
+	router.GET("/v1/mailboxes/:id/messages/search", h.searchMessages)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("邮件列表按头过滤（头名不区分大小写）", func(t *testing.T) {
+		w := get("/v1/mailboxes/mb-1/messages?header.x-test-run-id=4711")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data messageListResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, 1, resp.Data.Count)
+		assert.Equal(t, "run 4711", resp.Data.Items[0].Subject)
+		assert.Equal(t, map[string]string{"X-Test-Run-Id": "4711"}, resp.Data.Items[0].CapturedHeaders)
+	})
+
+	t.Run("搜索按头过滤", func(t *testing.T) {
+		w := get("/v1/mailboxes/mb-1/messages/search?header.X-Test-Run-ID=4712")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data domain.MessageSearchResult `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, 1, resp.Data.Total)
+		assert.Equal(t, "run 4712", resp.Data.Messages[0].Subject)
+	})
+
+	t.Run("没有匹配时返回空列表", func(t *testing.T) {
+		w := get("/v1/mailboxes/mb-1/messages?header.X-Test-Run-ID=9999")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"count":0`)
+	})
+
+	t.Run("无效的头名返回 400", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("/v1/mailboxes/mb-1/messages?header.X%22Run=1").Code)
+		assert.Equal(t, http.StatusBadRequest, get("/v1/mailboxes/mb-1/messages/search?header.=1").Code)
+	})
+}
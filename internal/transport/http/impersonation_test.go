@@ -0,0 +1,87 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/auth"
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestImpersonation_CredentialRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(time.Hour)
+	authService := auth.NewService(store)
+	jwtManager := jwtpkg.NewManager("test-secret-0123456789abcdefghijklmnop", "test", time.Hour, 24*time.Hour)
+	user, err := authService.Register(t.Context(), auth.RegisterInput{Email: "alice@example.com", Password: "password123", Username: "alice"})
+	require.NoError(t, err)
+
+	h := NewAuthHandler(authService, jwtManager)
+	jwtAuth := middleware.NewJWTAuth(jwtManager)
+
+	// 与 NewRouter 相同：凭据类接口所在的路由组拒绝代登录令牌
+	router := gin.New()
+	router.GET("/v1/auth/me", jwtAuth.RequireAuth(), h.Me)
+	credentialRoutes := router.Group("/v1/auth", jwtAuth.RequireAuth(), middleware.DenyImpersonation())
+	credentialRoutes.GET("/me/app-password", h.AppPassword)
+	credentialRoutes.POST("/me/app-password/reset", h.ResetAppPassword)
+
+	serve := func(method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	impersonation, err := jwtManager.GenerateImpersonationToken(user.ID, user.Email, string(user.Tier), "admin-1", 15*time.Minute)
+	require.NoError(t, err)
+
+	t.Run("代登录令牌读取应用密码返回 403", func(t *testing.T) {
+		w := serve(http.MethodGet, "/v1/auth/me/app-password", impersonation.AccessToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), middleware.ErrorCodeImpersonationForbidden)
+		assert.NotContains(t, w.Body.String(), jwtManager.AppPassword(user.ID, 0))
+	})
+
+	t.Run("代登录令牌仍可读取普通接口", func(t *testing.T) {
+		w := serve(http.MethodGet, "/v1/auth/me", impersonation.AccessToken)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("用户本人的令牌可以读取应用密码", func(t *testing.T) {
+		tokens, err := jwtManager.GenerateTokenPair(t.Context(), user.ID, user.Email, string(domain.TierFree))
+		require.NoError(t, err)
+		w := serve(http.MethodGet, "/v1/auth/me/app-password", tokens.AccessToken)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), jwtManager.AppPassword(user.ID, 0))
+	})
+
+	t.Run("重置后返回新密码，代登录令牌不能重置", func(t *testing.T) {
+		w := serve(http.MethodPost, "/v1/auth/me/app-password/reset", impersonation.AccessToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		tokens, err := jwtManager.GenerateTokenPair(t.Context(), user.ID, user.Email, string(domain.TierFree))
+		require.NoError(t, err)
+		w = serve(http.MethodPost, "/v1/auth/me/app-password/reset", tokens.AccessToken)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var reset appPasswordResponse
+		require.NoError(t, json.Unmarshal(mustData(t, w), &reset))
+		assert.Equal(t, jwtManager.AppPassword(user.ID, 1), reset.AppPassword)
+
+		w = serve(http.MethodGet, "/v1/auth/me/app-password", tokens.AccessToken)
+		assert.Contains(t, w.Body.String(), reset.AppPassword)
+		assert.NotContains(t, w.Body.String(), jwtManager.AppPassword(user.ID, 0))
+	})
+}
@@ -0,0 +1,49 @@
+package httptransport
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+)
+
+// JWTKeyHandler JWT 签名密钥管理API处理器
+type JWTKeyHandler struct {
+	keyService *service.JWTKeyService
+}
+
+// NewJWTKeyHandler 创建签名密钥处理器
+func NewJWTKeyHandler(keyService *service.JWTKeyService) *JWTKeyHandler {
+	return &JWTKeyHandler{
+		keyService: keyService,
+	}
+}
+
+// GetKeyStatus godoc
+// @Summary 获取 JWT 签名密钥状态
+// @Description 返回当前密钥ID、仍在验证期内的旧密钥ID及其失效时间（不返回密钥本身，需要管理员权限）
+// @Tags Admin - Auth
+// @Produce json
+// @Success 200 {object} Response{data=service.JWTKeyStatus}
+// @Router /v1/admin/auth/keys [get]
+func (h *JWTKeyHandler) GetKeyStatus(c *gin.Context) {
+	Success(c, h.keyService.Status())
+}
+
+// RotateSecret godoc
+// @Summary 轮换 JWT 签名密钥
+// @Description 生成新的签名密钥，当前密钥转为旧密钥并在刷新令牌有效期内继续验证，已登录用户无需重新登录；其他实例在一个配置轮询周期内同步（需要超级管理员权限）
+// @Tags Admin - Auth
+// @Produce json
+// @Success 200 {object} Response{data=service.JWTKeyStatus}
+// @Failure 403 {object} Response
+// @Failure 500 {object} Response
+// @Router /v1/admin/auth/rotate-secret [post]
+func (h *JWTKeyHandler) RotateSecret(c *gin.Context) {
+	status, err := h.keyService.Rotate(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		InternalError(c, MsgJWTRotateFailed)
+		return
+	}
+
+	SuccessWithMsg(c, "签名密钥已轮换", status)
+}
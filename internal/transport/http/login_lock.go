@@ -0,0 +1,72 @@
+package httptransport
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/auth"
+)
+
+// LoginLockHandler 用户登录锁定管理（供客服排查和解锁）
+type LoginLockHandler struct {
+	authService *auth.Service
+}
+
+// NewLoginLockHandler 创建登录锁定管理处理器
+func NewLoginLockHandler(authService *auth.Service) *LoginLockHandler {
+	return &LoginLockHandler{authService: authService}
+}
+
+// GetLoginLock godoc
+// @Summary 查看用户登录锁定状态
+// @Description 返回用户是否因连续登录失败被锁定、解锁时间和当前连续失败次数
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用户ID"
+// @Success 200 {object} Response{data=auth.LoginLockStatus}
+// @Failure 404 {object} Response
+// @Router /v1/admin/users/{id}/login-lock [get]
+func (h *LoginLockHandler) GetLoginLock(c *gin.Context) {
+	status, err := h.authService.LoginLockStatus(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			NotFound(c, MsgUserNotFound)
+			return
+		}
+		InternalError(c, MsgLoginLockQueryFailed)
+		return
+	}
+
+	Success(c, status)
+}
+
+// ClearLoginLock godoc
+// @Summary 解除用户登录锁定
+// @Description 解除账户锁定并清零连续失败次数（记录审计日志）
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用户ID"
+// @Success 200 {object} Response{data=auth.LoginLockStatus}
+// @Failure 404 {object} Response
+// @Router /v1/admin/users/{id}/login-lock [delete]
+func (h *LoginLockHandler) ClearLoginLock(c *gin.Context) {
+	userID := c.Param("id")
+	if err := h.authService.ClearLoginLock(c.Request.Context(), userID, c.GetString("userID")); err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			NotFound(c, MsgUserNotFound)
+			return
+		}
+		InternalError(c, MsgLoginLockClearFailed)
+		return
+	}
+
+	status, err := h.authService.LoginLockStatus(c.Request.Context(), userID)
+	if err != nil {
+		InternalError(c, MsgLoginLockQueryFailed)
+		return
+	}
+	Success(c, status)
+}
@@ -0,0 +1,91 @@
+package httptransport
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+)
+
+type batchCreateMailboxRequest struct {
+	Count     int     `json:"count" binding:"required"`
+	Pattern   string  `json:"pattern"`   // 可选：前缀模板，必须包含一次 {rand}，如 qa-{rand}
+	Domain    string  `json:"domain"`    // 可选：所有邮箱共用的域名
+	ExpiresIn string  `json:"expiresIn"` // 可选：所有邮箱共用的有效期
+	OrgID     *string `json:"orgId"`     // 可选：创建为组织邮箱
+}
+
+// batchCreateMailboxes godoc
+// @Summary 批量创建临时邮箱
+// @Description 在同一域名下一次创建 1~500 个随机地址的邮箱（需登录），共用有效期，返回每个邮箱的访问令牌。pattern 中的 {rand} 替换为 8 位随机字符。任一邮箱创建失败时不保留本次创建的邮箱
+// @Tags Mailboxes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body batchCreateMailboxRequest true "批量创建参数"
+// @Success 201 {object} mailboxListResponse
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 409 {object} Response "地址已被占用（data.reason=ADDRESS_TAKEN）"
+// @Router /v1/mailboxes/batch [post]
+func (h *Handler) batchCreateMailboxes(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	var req batchCreateMailboxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil {
+			BadRequest(c, MsgInvalidDuration)
+			return
+		}
+		t := time.Now().Add(d)
+		expiresAt = &t
+	}
+
+	mailboxes, err := h.mailboxes.CreateBatch(c.Request.Context(), service.BatchCreateMailboxInput{
+		Count:     req.Count,
+		Pattern:   req.Pattern,
+		Domain:    req.Domain,
+		IPSource:  c.ClientIP(),
+		UserID:    &userID,
+		OrgID:     req.OrgID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		if respondOrgError(c, err) || respondQuotaError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrBatchCountInvalid), errors.Is(err, service.ErrBatchPatternInvalid),
+			errors.Is(err, service.ErrDomainNotAllowed), errors.Is(err, service.ErrPrefixInvalid):
+			BadRequest(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrDomainExpired), errors.Is(err, service.ErrAddressNotWhitelisted),
+			errors.Is(err, service.ErrPrefixReserved):
+			Forbidden(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrAddressTaken):
+			respondAddressTaken(c)
+		default:
+			InternalError(c, MsgMailboxCreateFailed)
+		}
+		return
+	}
+
+	items := make([]mailboxResponse, 0, len(mailboxes))
+	for _, mailbox := range mailboxes {
+		items = append(items, toMailboxResponse(mailbox))
+	}
+	Created(c, mailboxListResponse{Items: items, Count: len(items)})
+}
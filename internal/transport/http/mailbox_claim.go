@@ -0,0 +1,61 @@
+package httptransport
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// claimMailboxRequest 认领游客邮箱请求（令牌也可以放在 X-Mailbox-Token 头中）
+type claimMailboxRequest struct {
+	Token string `json:"token"`
+}
+
+// claimMailbox godoc
+// @Summary 认领游客邮箱
+// @Description 将游客创建的邮箱归入当前登录用户名下（凭创建时返回的邮箱令牌），之后出现在邮箱列表中并按用户等级计算配额。
+// @Description 计入个人邮箱数量配额；有效期超过用户等级最长生存时间的截断到上限。公开收件箱不能认领
+// @Tags Mailboxes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "邮箱ID"
+// @Param X-Mailbox-Token header string false "邮箱令牌"
+// @Param request body claimMailboxRequest false "邮箱令牌"
+// @Success 200 {object} mailboxResponse
+// @Failure 401 {object} Response "未登录"
+// @Failure 403 {object} Response "邮箱令牌无效、公开收件箱、邮箱已停用或个人邮箱数量已达上限"
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response "邮箱已属于其他账户"
+// @Router /v1/mailboxes/{id}/claim [post]
+func (h *Handler) claimMailbox(c *gin.Context) {
+	var req claimMailboxRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+	}
+	token := c.GetHeader("X-Mailbox-Token")
+	if token == "" {
+		token = req.Token
+	}
+
+	mailbox, err := h.mailboxes.Claim(c.Request.Context(), c.Param("id"), token, c.GetString("userID"))
+	switch {
+	case err == nil:
+		Success(c, toMailboxResponse(mailbox))
+	case respondQuotaError(c, err):
+	case errors.Is(err, service.ErrClaimTokenInvalid), errors.Is(err, service.ErrClaimPublicInbox), errors.Is(err, service.ErrMailboxSuspended):
+		Forbidden(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrMailboxAlreadyOwned):
+		Conflict(c, GetErrorMessage(err))
+	case errors.Is(err, memory.ErrMailboxNotFound):
+		NotFound(c, MsgMailboxNotFound)
+	default:
+		InternalError(c, MsgInternalError)
+	}
+}
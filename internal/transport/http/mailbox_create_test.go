@@ -0,0 +1,116 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestCreateMailbox_AddressTaken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.mail"}, DefaultTTL: time.Hour}}
+	h := &Handler{
+		mailboxes: service.NewMailboxService(store, store, cfg),
+		aliases:   service.NewAliasService(store, store, cfg),
+	}
+	router := gin.New()
+	router.POST("/v1/mailboxes", h.createMailbox)
+	router.POST("/v1/mailboxes/:id/aliases", h.createAlias)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	reason := func(w *httptest.ResponseRecorder) string {
+		var resp struct {
+			Data struct {
+				Reason string `json:"reason"`
+			} `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Data.Reason
+	}
+
+	t.Run("并发创建同一地址只有一个成功", func(t *testing.T) {
+		const concurrency = 20
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		results := make([]*httptest.ResponseRecorder, concurrency)
+		for i := range concurrency {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				results[i] = post("/v1/mailboxes", `{"prefix":"race","domain":"temp.mail"}`)
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		var created []*httptest.ResponseRecorder
+		for _, w := range results {
+			switch w.Code {
+			case http.StatusCreated:
+				created = append(created, w)
+			case http.StatusConflict:
+				assert.Equal(t, ReasonAddressTaken, reason(w))
+			default:
+				t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+			}
+		}
+		require.Len(t, created, 1)
+
+		var resp struct {
+			Data mailboxResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(created[0].Body.Bytes(), &resp))
+		stored, err := store.GetMailboxByAddress(t.Context(), "race@temp.mail")
+		require.NoError(t, err)
+		assert.Equal(t, resp.Data.ID, stored.ID)
+		assert.Equal(t, resp.Data.Token, stored.Token)
+
+		count := 0
+		for _, mailbox := range store.ListMailboxes(t.Context()) {
+			if mailbox.Address == "race@temp.mail" {
+				count++
+			}
+		}
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("别名不能使用已有邮箱的地址", func(t *testing.T) {
+		w := post("/v1/mailboxes", `{"prefix":"owner","domain":"temp.mail"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var resp struct {
+			Data mailboxResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+		w = post("/v1/mailboxes/"+resp.Data.ID+"/aliases", `{"address":"race@temp.mail"}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		assert.Equal(t, ReasonAddressTaken, reason(w))
+
+		w = post("/v1/mailboxes/"+resp.Data.ID+"/aliases", `{"address":"other@temp.mail"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		w = post("/v1/mailboxes", `{"prefix":"other","domain":"temp.mail"}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		assert.Equal(t, ReasonAddressTaken, reason(w))
+	})
+}
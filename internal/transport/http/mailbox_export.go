@@ -0,0 +1,46 @@
+package httptransport
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// exportMailbox godoc
+// @Summary 导出邮箱
+// @Description 以附件形式流式下载邮箱中的全部邮件（含隔离区，按接收时间排序）。mbox 为单个 mboxrd 文件；eml-zip 为 zip 压缩包，每封邮件一个 .eml，附件另存于同名目录。适合在邮箱过期前留存验证邮件
+// @Tags Mailboxes
+// @Produce application/mbox
+// @Produce application/zip
+// @Param id path string true "邮箱ID"
+// @Param format query string false "导出格式：mbox（默认）或 eml-zip"
+// @Success 200 {file} binary
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/mailboxes/{id}/export [get]
+func (h *Handler) exportMailbox(c *gin.Context) {
+	format := c.DefaultQuery("format", service.MailboxExportMbox)
+	if !h.export.ValidFormat(format) {
+		BadRequest(c, GetErrorMessage(service.ErrExportFormatInvalid))
+		return
+	}
+	value, _ := c.Get("mailbox")
+	mailbox := value.(*domain.Mailbox)
+
+	contentType := "application/mbox"
+	if format == service.MailboxExportEMLZip {
+		contentType = "application/zip"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", h.export.Filename(mailbox, format)))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	// 已开始写响应，出错时只能中断连接
+	if err := h.export.Export(c.Request.Context(), mailbox.ID, format, c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
@@ -0,0 +1,138 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// createForwardRequest 添加转发地址请求
+type createForwardRequest struct {
+	Address string `json:"address" binding:"required"`
+}
+
+// verifyForwardRequest 确认转发地址请求
+type verifyForwardRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// createMailboxForward godoc
+// @Summary 添加转发地址
+// @Description 向外部地址发送确认码，确认后该邮箱收到的邮件会经发信中继复制一份转发过去。地址已在等待确认时重发确认码。每个邮箱最多 5 个转发地址，默认不允许游客邮箱使用，不能转发到本实例的域名
+// @Tags Mailboxes
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param request body createForwardRequest true "目标地址"
+// @Success 201 {object} Response{data=domain.MailboxForward}
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 409 {object} Response
+// @Failure 502 {object} Response
+// @Router /v1/mailboxes/{id}/forwards [post]
+func (h *Handler) createMailboxForward(c *gin.Context) {
+	var req createForwardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	mailbox, _ := c.Get("mailbox")
+
+	forward, err := h.forwards.Add(c.Request.Context(), mailbox.(*domain.Mailbox), req.Address)
+	switch {
+	case err == nil:
+		Created(c, forward)
+	case errors.Is(err, service.ErrForwardAddressInvalid), errors.Is(err, service.ErrForwardAddressLocal):
+		BadRequest(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrForwardRequiresAccount):
+		Forbidden(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrForwardLimit), errors.Is(err, service.ErrForwardVerified):
+		Conflict(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrSendRelayFailed):
+		Error(c, http.StatusBadGateway, GetErrorMessage(service.ErrSendRelayFailed))
+	default:
+		InternalError(c, MsgForwardUpdateFailed)
+	}
+}
+
+// listMailboxForwards godoc
+// @Summary 转发地址列表
+// @Description 列出邮箱的转发地址及其确认状态
+// @Tags Mailboxes
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Success 200 {object} Response{data=[]domain.MailboxForward}
+// @Router /v1/mailboxes/{id}/forwards [get]
+func (h *Handler) listMailboxForwards(c *gin.Context) {
+	forwards, err := h.forwards.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		InternalError(c, MsgForwardListFailed)
+		return
+	}
+
+	Success(c, gin.H{
+		"items": forwards,
+		"count": len(forwards),
+	})
+}
+
+// verifyMailboxForward godoc
+// @Summary 确认转发地址
+// @Description 提交发往目标地址的确认码。确认码 24 小时内有效，最多尝试 5 次，过期或用尽后重新添加该地址获取新的确认码
+// @Tags Mailboxes
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param forwardId path string true "转发地址ID"
+// @Param request body verifyForwardRequest true "确认码"
+// @Success 200 {object} Response{data=domain.MailboxForward}
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/mailboxes/{id}/forwards/{forwardId}/verify [post]
+func (h *Handler) verifyMailboxForward(c *gin.Context) {
+	var req verifyForwardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	forward, err := h.forwards.Verify(c.Request.Context(), c.Param("id"), c.Param("forwardId"), req.Code)
+	switch {
+	case err == nil:
+		Success(c, forward)
+	case errors.Is(err, service.ErrForwardNotFound):
+		NotFound(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrForwardCodeInvalid), errors.Is(err, service.ErrForwardCodeExpired):
+		BadRequest(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrForwardVerified):
+		Conflict(c, GetErrorMessage(err))
+	default:
+		InternalError(c, MsgForwardUpdateFailed)
+	}
+}
+
+// deleteMailboxForward godoc
+// @Summary 删除转发地址
+// @Description 停止转发到该地址，尚未完成的转发投递一并取消
+// @Tags Mailboxes
+// @Param id path string true "邮箱ID"
+// @Param forwardId path string true "转发地址ID"
+// @Success 204
+// @Failure 404 {object} Response
+// @Router /v1/mailboxes/{id}/forwards/{forwardId} [delete]
+func (h *Handler) deleteMailboxForward(c *gin.Context) {
+	err := h.forwards.Remove(c.Request.Context(), c.Param("id"), c.Param("forwardId"))
+	switch {
+	case err == nil:
+		NoContent(c)
+	case errors.Is(err, service.ErrForwardNotFound):
+		NotFound(c, GetErrorMessage(err))
+	default:
+		InternalError(c, MsgForwardUpdateFailed)
+	}
+}
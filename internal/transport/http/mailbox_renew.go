@@ -0,0 +1,68 @@
+package httptransport
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// extendMailboxRequest 延长邮箱有效期请求
+type extendMailboxRequest struct {
+	ExpiresIn string `json:"expiresIn"` // 可选：延长时长（如 "24h"），默认使用邮箱默认有效期
+}
+
+// extendMailbox godoc
+// @Summary 延长邮箱有效期
+// @Description 从当前过期时间顺延（已过期部分从现在算起），仅注册用户的邮箱可用。
+// @Description 延长后不能超过用户等级的最长生存时间（从创建时间算起：free 24 小时、basic 7 天、pro 30 天、enterprise 不限）
+// @Tags Mailboxes
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param request body extendMailboxRequest false "延长时长"
+// @Success 200 {object} mailboxResponse
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/mailboxes/{id}/extend [post]
+func (h *Handler) extendMailbox(c *gin.Context) {
+	var req extendMailboxRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+	}
+	var by time.Duration
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil {
+			BadRequest(c, MsgInvalidDuration)
+			return
+		}
+		if d <= 0 {
+			BadRequest(c, GetErrorMessage(service.ErrExtendDurationInvalid))
+			return
+		}
+		by = d
+	}
+
+	mailbox, err := h.mailboxes.Extend(c.Request.Context(), c.Param("id"), by)
+	switch {
+	case err == nil:
+		Success(c, toMailboxResponse(mailbox))
+	case errors.Is(err, service.ErrRenewRequiresAccount):
+		Forbidden(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrMailboxLifetimeExceeded):
+		Conflict(c, GetErrorMessage(err))
+	case errors.Is(err, memory.ErrMailboxNotFound):
+		NotFound(c, MsgMailboxNotFound)
+	default:
+		InternalError(c, MsgInternalError)
+	}
+}
@@ -0,0 +1,152 @@
+package httptransport
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+)
+
+// createMailboxShareRequest 创建邮箱分享链接请求
+type createMailboxShareRequest struct {
+	ExpiresIn        string `json:"expiresIn,omitempty"` // 有效期，如 "2h"、"72h"（默认 24h，最长 168h）
+	AllowAttachments bool   `json:"allowAttachments"`
+}
+
+// createMailboxShare godoc
+// @Summary 创建邮箱分享链接
+// @Description 为整个邮箱生成限时只读链接（签名令牌），持有链接即可查看邮箱中的全部邮件（不含隔离区），但不能修改邮箱，也不会暴露邮箱令牌。可随时撤销
+// @Tags Mailboxes
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param request body createMailboxShareRequest false "有效期和选项"
+// @Success 201 {object} Response{data=messageShareResponse}
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/mailboxes/{id}/shares [post]
+func (h *Handler) createMailboxShare(c *gin.Context) {
+	var req createMailboxShareRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+	}
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		duration, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil {
+			BadRequest(c, GetErrorMessage(service.ErrShareExpiryInvalid))
+			return
+		}
+		ttl = duration
+	}
+
+	link, err := h.shares.CreateMailbox(c.Request.Context(), c.Param("id"), service.CreateShareInput{
+		TTL:              ttl,
+		AllowAttachments: req.AllowAttachments,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrShareExpiryInvalid):
+			BadRequest(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrShareMailboxNotFound):
+			NotFound(c, GetErrorMessage(err))
+		default:
+			InternalError(c, MsgMessageShareFailed)
+		}
+		return
+	}
+
+	Created(c, newMessageShareResponse(*link))
+}
+
+// GetMailbox godoc
+// @Summary 查看分享的邮箱
+// @Description 凭邮箱分享链接分页列出邮件预览（新邮件在前），每次查看计入访问次数
+// @Tags Public
+// @Produce json
+// @Param token path string true "分享令牌"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量（最大50）" default(20)
+// @Success 200 {object} Response{data=service.SharedMailbox}
+// @Failure 404 {object} Response
+// @Failure 410 {object} Response
+// @Failure 429 {object} Response
+// @Router /v1/shared/mailboxes/{token} [get]
+func (h *SharedMessageHandler) GetMailbox(c *gin.Context) {
+	noStore(c)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(service.DefaultPublicPageSize)))
+
+	mailbox, err := h.shares.OpenMailbox(c.Request.Context(), c.Param("token"), page, pageSize)
+	if err != nil {
+		if !h.respondError(c, err) {
+			InternalError(c, MsgMessageListFailed)
+		}
+		return
+	}
+
+	Success(c, mailbox)
+}
+
+// GetMailboxMessage godoc
+// @Summary 查看分享邮箱中的邮件
+// @Description 凭邮箱分享链接查看单封邮件（HTML 已清理，不标记已读）
+// @Tags Public
+// @Produce json
+// @Param token path string true "分享令牌"
+// @Param messageId path string true "邮件ID"
+// @Success 200 {object} Response{data=service.SharedMessage}
+// @Failure 404 {object} Response
+// @Failure 410 {object} Response
+// @Router /v1/shared/mailboxes/{token}/messages/{messageId} [get]
+func (h *SharedMessageHandler) GetMailboxMessage(c *gin.Context) {
+	noStore(c)
+	message, err := h.shares.OpenMailboxMessage(c.Request.Context(), c.Param("token"), c.Param("messageId"))
+	if err != nil {
+		if !h.respondError(c, err) {
+			InternalError(c, MsgMessageGetFailed)
+		}
+		return
+	}
+
+	Success(c, message)
+}
+
+// DownloadMailboxAttachment godoc
+// @Summary 下载分享邮箱中的附件
+// @Description 仅创建链接时允许下载附件才可用
+// @Tags Public
+// @Produce application/octet-stream
+// @Param token path string true "分享令牌"
+// @Param messageId path string true "邮件ID"
+// @Param attachmentId path string true "附件ID"
+// @Success 200 {file} binary
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 410 {object} Response
+// @Router /v1/shared/mailboxes/{token}/messages/{messageId}/attachments/{attachmentId} [get]
+func (h *SharedMessageHandler) DownloadMailboxAttachment(c *gin.Context) {
+	noStore(c)
+	attachment, err := h.shares.OpenMailboxAttachment(c.Request.Context(), c.Param("token"), c.Param("messageId"), c.Param("attachmentId"))
+	if err != nil {
+		if !h.respondError(c, err) {
+			NotFound(c, MsgAttachmentNotFound)
+		}
+		return
+	}
+
+	content, err := attachment.Open()
+	if err != nil {
+		InternalError(c, MsgAttachmentNotFound)
+		return
+	}
+	defer content.Close()
+
+	serveAttachment(c, attachment, content)
+}
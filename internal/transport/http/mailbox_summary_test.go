@@ -0,0 +1,94 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestListMailboxes_Summary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	userID := "user-1"
+	created := time.Now().Add(-time.Hour)
+	for i, address := range []string{"b@temp.mail", "a@temp.mail", "c@temp.mail"} {
+		require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+			ID: "mb-" + address[:1], Address: address, UserID: &userID,
+			CreatedAt: created.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	require.NoError(t, store.SaveMessage(t.Context(), &domain.Message{
+		ID: "msg-1", MailboxID: "mb-a", From: "sender@example.com", Subject: "hello", Size: 42, ReceivedAt: time.Now(),
+	}))
+
+	h := &Handler{mailboxes: service.NewMailboxService(store, store, &config.Config{})}
+	router := gin.New()
+	router.GET("/v1/mailboxes", func(c *gin.Context) { c.Set("userID", userID) }, h.listMailboxes)
+
+	list := func(query string) (int, []map[string]interface{}) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/mailboxes"+query, nil))
+		var resp struct {
+			Data struct {
+				Items []map[string]interface{} `json:"items"`
+			} `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Data.Items
+	}
+	addresses := func(items []map[string]interface{}) []string {
+		result := make([]string, 0, len(items))
+		for _, item := range items {
+			result = append(result, item["address"].(string))
+		}
+		return result
+	}
+
+	t.Run("默认返回摘要并按最近活动排序", func(t *testing.T) {
+		code, items := list("")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"a@temp.mail", "c@temp.mail", "b@temp.mail"}, addresses(items))
+		assert.Equal(t, float64(1), items[0]["total"])
+		assert.Equal(t, float64(1), items[0]["unread"])
+		assert.Equal(t, float64(42), items[0]["storageBytes"])
+		preview := items[0]["lastMessage"].(map[string]interface{})
+		assert.Equal(t, "hello", preview["subject"])
+		assert.Equal(t, "sender@example.com", preview["from"])
+		assert.NotEmpty(t, items[1]["lastActivityAt"])
+		assert.Nil(t, items[1]["lastMessage"])
+	})
+
+	t.Run("按创建时间和地址排序", func(t *testing.T) {
+		_, items := list("?sort=createdAt")
+		assert.Equal(t, []string{"c@temp.mail", "a@temp.mail", "b@temp.mail"}, addresses(items))
+		_, items = list("?sort=address")
+		assert.Equal(t, []string{"a@temp.mail", "b@temp.mail", "c@temp.mail"}, addresses(items))
+	})
+
+	t.Run("不支持的排序方式", func(t *testing.T) {
+		code, _ := list("?sort=size")
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("summary=false 保留原有列表", func(t *testing.T) {
+		code, items := list("?summary=false")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, items, 3)
+		for _, item := range items {
+			assert.NotContains(t, item, "lastActivityAt")
+			assert.NotContains(t, item, "lastMessage")
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestMailboxWebhookRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	for _, mb := range []domain.Mailbox{
+		{ID: "mb-a", Address: "a@temp.mail", LocalPart: "a", Domain: "temp.mail", Token: "tok-a"},
+		{ID: "mb-b", Address: "b@temp.mail", LocalPart: "b", Domain: "temp.mail", Token: "tok-b"},
+	} {
+		mailbox := mb
+		mailbox.CreatedAt = time.Now()
+		require.NoError(t, store.SaveMailbox(t.Context(), &mailbox))
+	}
+	cfg := &config.Config{}
+	cfg.CORS.AllowedOrigins = []string{"*"}
+	router := NewRouter(RouterDependencies{
+		Config:         cfg,
+		MailboxService: service.NewMailboxService(store, store, cfg),
+		MessageService: service.NewMessageService(store),
+		WebhookService: service.NewWebhookService(store),
+		Store:          store,
+	})
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("X-Mailbox-Token", token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("只凭邮箱Token创建、列出和删除", func(t *testing.T) {
+		w := do(http.MethodPost, "/v1/mailboxes/mb-a/webhooks", "tok-a", `{"url":"https://hooks.example.com/mail","events":["mail.received","mailbox.expired"]}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created struct {
+			Data domain.Webhook `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		assert.Equal(t, domain.WebhookOwnerMailbox, created.Data.OwnerType)
+		assert.Equal(t, "mb-a", created.Data.OwnerID)
+		assert.NotEmpty(t, created.Data.Secret)
+
+		w = do(http.MethodGet, "/v1/mailboxes/mb-a/webhooks", "tok-a", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), created.Data.ID)
+
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/v1/mailboxes/mb-b/webhooks/"+created.Data.ID, "tok-b", "").Code)
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/v1/mailboxes/mb-a/webhooks/"+created.Data.ID, "tok-a", "").Code)
+	})
+
+	t.Run("没有Token或使用其他邮箱的Token被拒绝", func(t *testing.T) {
+		body := `{"url":"https://hooks.example.com/mail"}`
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/v1/mailboxes/mb-a/webhooks", "", body).Code)
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/v1/mailboxes/mb-a/webhooks", "tok-b", body).Code)
+	})
+
+	t.Run("拒绝内网地址、不支持的事件和超出上限", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/v1/mailboxes/mb-b/webhooks", "tok-b", `{"url":"http://127.0.0.1:8080/"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/v1/mailboxes/mb-b/webhooks", "tok-b", `{"url":"https://hooks.example.com/","events":["tag.created"]}`).Code)
+
+		limit := domain.DefaultGuestWebhookConfig().MaxPerMailbox
+		for i := 0; i < limit; i++ {
+			require.Equal(t, http.StatusCreated, do(http.MethodPost, "/v1/mailboxes/mb-b/webhooks", "tok-b", `{"url":"https://hooks.example.com/"}`).Code)
+		}
+		assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/v1/mailboxes/mb-b/webhooks", "tok-b", `{"url":"https://hooks.example.com/"}`).Code)
+	})
+}
@@ -0,0 +1,68 @@
+package httptransport
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/mailflow"
+)
+
+// MailFlowHandler 管理员收信流量看板处理器
+type MailFlowHandler struct {
+	recorder *mailflow.Recorder
+}
+
+// NewMailFlowHandler 创建收信流量看板处理器
+func NewMailFlowHandler(recorder *mailflow.Recorder) *MailFlowHandler {
+	return &MailFlowHandler{recorder: recorder}
+}
+
+// Report godoc
+// @Summary 收信流量看板
+// @Description 返回 SMTP 收信的时间序列（每个时间点的接收数和拒收数）、平均/峰值每分钟接收数、按原因的拒收合计和发件/收件域名排行（最多延迟一个写入间隔）
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param interval query string false "粒度：minute 或 hour（默认 minute）"
+// @Param from query string false "开始时间（RFC3339，默认按分钟为 1 小时前、按小时为 24 小时前）"
+// @Param to query string false "结束时间（RFC3339，默认当前时间）"
+// @Param top query int false "域名排行返回的数量（默认 10，最多 100）"
+// @Success 200 {object} Response{data=mailflow.Report}
+// @Failure 400 {object} Response
+// @Router /v1/admin/mailflow [get]
+func (h *MailFlowHandler) Report(c *gin.Context) {
+	from, errFrom := parseAnalyticsTime(c.Query("from"))
+	to, errTo := parseAnalyticsTime(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		BadRequest(c, GetErrorMessage(mailflow.ErrInvalidTimeRange))
+		return
+	}
+	top := 0
+	if raw := c.Query("top"); raw != "" {
+		var err error
+		if top, err = strconv.Atoi(raw); err != nil || top < 1 {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+	}
+
+	report, err := h.recorder.Query(c.Request.Context(), mailflow.Query{
+		Interval: c.Query("interval"),
+		From:     from,
+		To:       to,
+		Top:      top,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, mailflow.ErrInvalidInterval), errors.Is(err, mailflow.ErrInvalidTimeRange),
+			errors.Is(err, mailflow.ErrTimeRangeTooLong):
+			BadRequest(c, GetErrorMessage(err))
+		default:
+			InternalError(c, MsgMailFlowQueryFailed)
+		}
+		return
+	}
+	Success(c, report)
+}
@@ -0,0 +1,58 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/mailflow"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestMailFlowHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := mailflow.NewRecorder(memory.NewStore(time.Hour), config.MailFlowConfig{Enabled: true}, nil)
+	now := time.Date(2026, 3, 2, 12, 30, 0, 0, time.UTC)
+	recorder.SetClock(func() time.Time { return now })
+	recorder.RecordMessage("a@sender.com", []string{"x@temp.mail"})
+	recorder.RecordRejection("greylisted")
+	require.NoError(t, recorder.Flush(t.Context()))
+
+	router := gin.New()
+	router.GET("/v1/admin/mailflow", NewMailFlowHandler(recorder).Report)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("按分钟返回流量报告", func(t *testing.T) {
+		w := get("/v1/admin/mailflow?from=2026-03-02T12:00:00Z&to=2026-03-02T13:00:00Z&top=5")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Data mailflow.Report `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, mailflow.IntervalMinute, resp.Data.Interval)
+		assert.Len(t, resp.Data.Points, 60)
+		assert.Equal(t, mailflow.Totals{Accepted: 1, Rejected: 1}, resp.Data.Totals)
+		assert.Equal(t, []mailflow.Count{{Name: "sender.com", Count: 1}}, resp.Data.TopSenderDomains)
+		assert.Equal(t, []mailflow.Count{{Name: "temp.mail", Count: 1}}, resp.Data.TopRecipientDomains)
+		assert.Equal(t, []mailflow.Count{{Name: "greylisted", Count: 1}}, resp.Data.Rejections)
+	})
+
+	t.Run("无效参数返回 400", func(t *testing.T) {
+		for _, query := range []string{"interval=day", "from=yesterday", "top=0", "interval=minute&from=2026-03-01T00:00:00Z"} {
+			assert.Equal(t, http.StatusBadRequest, get("/v1/admin/mailflow?"+query).Code, query)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package httptransport
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/jobs"
+)
+
+// MaintenanceJobHandler 管理员维护任务（回填、重新统计）处理器
+type MaintenanceJobHandler struct {
+	runner *jobs.Runner
+}
+
+// NewMaintenanceJobHandler 创建维护任务处理器
+func NewMaintenanceJobHandler(runner *jobs.Runner) *MaintenanceJobHandler {
+	return &MaintenanceJobHandler{runner: runner}
+}
+
+// CreateMaintenanceJobRequest 创建维护任务请求
+type CreateMaintenanceJobRequest struct {
+	Type   string            `json:"type" binding:"required"` // backfill-previews 或 recount-mailboxes
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Create godoc
+// @Summary 创建维护任务
+// @Description 在后台分批执行维护任务：backfill-previews 为只有 HTML 正文的历史邮件生成纯文本，recount-mailboxes 按邮件重新统计邮箱的总数和未读数。同类型同时只能有一个排队或运行中的任务
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateMaintenanceJobRequest true "任务类型"
+// @Success 201 {object} Response{data=domain.MaintenanceJob}
+// @Failure 400 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/admin/jobs [post]
+func (h *MaintenanceJobHandler) Create(c *gin.Context) {
+	var req CreateMaintenanceJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	job, err := h.runner.Enqueue(c.Request.Context(), domain.MaintenanceJobType(req.Type), req.Params, c.GetString("userID"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	Created(c, job)
+}
+
+// List godoc
+// @Summary 维护任务列表
+// @Description 列出维护任务及进度（新任务在前）
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=[]domain.MaintenanceJob}
+// @Router /v1/admin/jobs [get]
+func (h *MaintenanceJobHandler) List(c *gin.Context) {
+	list, err := h.runner.List(c.Request.Context())
+	if err != nil {
+		InternalError(c, MsgMaintenanceJobFailed)
+		return
+	}
+
+	Success(c, list)
+}
+
+// Get godoc
+// @Summary 维护任务详情
+// @Description 返回任务状态和进度：total 为开始时统计的记录数（估算），processed 为已扫描数，updated 为实际修改数
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "任务ID"
+// @Success 200 {object} Response{data=domain.MaintenanceJob}
+// @Failure 404 {object} Response
+// @Router /v1/admin/jobs/{id} [get]
+func (h *MaintenanceJobHandler) Get(c *gin.Context) {
+	job, err := h.runner.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	Success(c, job)
+}
+
+// Cancel godoc
+// @Summary 取消维护任务
+// @Description 排队中的任务立即取消；运行中的任务在当前批结束前停止，已处理的批次保留
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "任务ID"
+// @Success 200 {object} Response{data=domain.MaintenanceJob}
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/admin/jobs/{id}/cancel [post]
+func (h *MaintenanceJobHandler) Cancel(c *gin.Context) {
+	job, err := h.runner.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	Success(c, job)
+}
+
+func (h *MaintenanceJobHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrUnknownJobType):
+		BadRequest(c, GetErrorMessage(err))
+	case errors.Is(err, jobs.ErrJobNotFound):
+		NotFound(c, GetErrorMessage(err))
+	case errors.Is(err, jobs.ErrJobActive), errors.Is(err, jobs.ErrJobFinished):
+		Conflict(c, GetErrorMessage(err))
+	default:
+		InternalError(c, MsgMaintenanceJobFailed)
+	}
+}
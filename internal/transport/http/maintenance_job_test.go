@@ -0,0 +1,90 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/jobs"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestMaintenanceJobHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "box@temp.mail", LocalPart: "box", Domain: "temp.mail", Token: "secret-token",
+		CreatedAt: time.Now(), TotalCount: 3, Unread: 3,
+	}))
+	runner := jobs.NewRunner(store, nil)
+	runner.Register(domain.MaintenanceJobRecountMailboxes, jobs.NewRecountMailboxes(store))
+	h := NewMaintenanceJobHandler(runner)
+
+	router := gin.New()
+	admin := router.Group("/v1/admin", func(c *gin.Context) { c.Set("userID", "admin-1") })
+	admin.POST("/jobs", h.Create)
+	admin.GET("/jobs", h.List)
+	admin.GET("/jobs/:id", h.Get)
+	admin.POST("/jobs/:id/cancel", h.Cancel)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) domain.MaintenanceJob {
+		t.Helper()
+		var resp struct {
+			Data domain.MaintenanceJob `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+		return resp.Data
+	}
+
+	t.Run("创建任务并校验类型", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/v1/admin/jobs", `{}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/v1/admin/jobs", `{"type":"reindex-everything"}`).Code)
+
+		w := do(http.MethodPost, "/v1/admin/jobs", `{"type":"recount-mailboxes"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		job := decode(t, w)
+		assert.Equal(t, domain.MaintenanceJobQueued, job.Status)
+		assert.Equal(t, "admin-1", job.CreatedBy)
+		assert.NotContains(t, w.Body.String(), "activeType")
+
+		assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/v1/admin/jobs", `{"type":"recount-mailboxes"}`).Code, "同类型已有排队任务")
+
+		w = do(http.MethodGet, "/v1/admin/jobs/"+job.ID, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, job.ID, decode(t, w).ID)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/v1/admin/jobs/missing", "").Code)
+	})
+
+	t.Run("取消任务", func(t *testing.T) {
+		w := do(http.MethodGet, "/v1/admin/jobs", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []domain.MaintenanceJob `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Data, 1)
+		id := resp.Data[0].ID
+
+		w = do(http.MethodPost, "/v1/admin/jobs/"+id+"/cancel", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, domain.MaintenanceJobCancelled, decode(t, w).Status)
+		assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/v1/admin/jobs/"+id+"/cancel", "").Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/v1/admin/jobs/missing/cancel", "").Code)
+	})
+}
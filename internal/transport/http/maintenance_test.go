@@ -0,0 +1,135 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/hybrid"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// maintenanceInstance 一个服务实例（路由、配置服务）
+type maintenanceInstance struct {
+	router  *gin.Engine
+	configs *service.ConfigService
+}
+
+func TestReadOnlyMaintenance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 两个实例共享数据库和缓存（缓存在生产环境中为 Redis，系统配置经缓存在实例间共享）
+	dbPath := filepath.Join(t.TempDir(), "tempmail.db")
+	sharedCache := memory.NewStore(time.Hour)
+	jwtManager := jwtpkg.NewManager("test-secret-0123456789abcdefghijklmnop", "test", time.Hour, 24*time.Hour)
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.mail"}, DefaultTTL: time.Hour}}
+
+	newInstance := func() *maintenanceInstance {
+		store, err := hybrid.NewStandaloneStore("sqlite", dbPath, sharedCache)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = store.Close() })
+
+		configs := service.NewConfigService(store)
+		mailboxes := service.NewMailboxService(store, store, cfg)
+		audit := service.NewAuditService(store)
+		h := &Handler{mailboxes: mailboxes}
+		configHandler := NewConfigHandler(configs)
+
+		router := gin.New()
+		router.Use(middleware.ReadOnlyMode(configs))
+		router.POST("/v1/mailboxes", h.createMailbox)
+		router.GET("/v1/mailboxes/:id", middleware.NewMailboxAuth(mailboxes).RequireMailboxToken(), h.getMailbox)
+		admin := router.Group("/v1/admin", middleware.NewJWTAuth(jwtManager).RequireAuth(), middleware.AdminAudit(audit, nil))
+		admin.POST("/maintenance", configHandler.SetMaintenance)
+		admin.GET("/audit-logs", NewAuditLogHandler(audit).List)
+		return &maintenanceInstance{router: router, configs: configs}
+	}
+	primary := newInstance()
+	secondary := newInstance()
+
+	adminTokens, err := jwtManager.GenerateTokenPair(t.Context(), "admin-1", "admin@example.com", string(domain.TierFree))
+	require.NoError(t, err)
+	do := func(inst *maintenanceInstance, method, path, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		inst.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 维护前创建一个邮箱，供只读期间读取
+	w := do(primary, http.MethodPost, "/v1/mailboxes", "", `{"prefix":"before","domain":"temp.mail"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Data mailboxResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	w = do(primary, http.MethodPost, "/v1/admin/maintenance", adminTokens.AccessToken, `{"readOnly":true,"message":"数据库迁移中"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	t.Run("创建邮箱返回 503 和自定义说明", func(t *testing.T) {
+		w := do(primary, http.MethodPost, "/v1/mailboxes", "", `{"prefix":"during","domain":"temp.mail"}`)
+		data := assertThrottled(t, w, http.StatusServiceUnavailable, middleware.ErrorCodeMaintenance)
+		assert.Equal(t, true, data["readOnly"])
+		assert.Contains(t, w.Body.String(), "数据库迁移中")
+	})
+
+	t.Run("读请求不受影响", func(t *testing.T) {
+		w := do(primary, http.MethodGet, "/v1/mailboxes/"+created.Data.ID, created.Data.Token, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("共享存储的其他实例刷新后同样只读", func(t *testing.T) {
+		secondary.configs.RefreshRuntimeConfig(t.Context())
+		w := do(secondary, http.MethodPost, "/v1/mailboxes", "", `{"prefix":"other","domain":"temp.mail"}`)
+		assertThrottled(t, w, http.StatusServiceUnavailable, middleware.ErrorCodeMaintenance)
+
+		w = do(secondary, http.MethodGet, "/v1/mailboxes/"+created.Data.ID, created.Data.Token, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("切换记入审计日志", func(t *testing.T) {
+		w := do(primary, http.MethodGet, "/v1/admin/audit-logs?action="+service.AuditMaintenanceSet, adminTokens.AccessToken, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Data service.AuditLogPage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Data.Items, 1)
+		entry := resp.Data.Items[0]
+		assert.Equal(t, "admin-1", entry.ActorID)
+		assert.Equal(t, domain.AuditTargetConfig, entry.TargetType)
+
+		changes := make(map[string]domain.AuditChange)
+		for _, change := range entry.Changes {
+			changes[change.Field] = change
+		}
+		require.Contains(t, changes, "readOnly")
+		assert.Equal(t, true, changes["readOnly"].To)
+	})
+
+	t.Run("关闭后恢复写入", func(t *testing.T) {
+		w := do(primary, http.MethodPost, "/v1/admin/maintenance", adminTokens.AccessToken, `{"readOnly":false}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = do(primary, http.MethodPost, "/v1/mailboxes", "", `{"prefix":"after","domain":"temp.mail"}`)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	})
+}
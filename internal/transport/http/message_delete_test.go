@@ -0,0 +1,85 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// mailboxUpdates 记录推送的 mailbox_update
+type mailboxUpdates struct {
+	mailboxes []domain.Mailbox
+}
+
+func (u *mailboxUpdates) NotifyMailboxUpdate(mailbox *domain.Mailbox) {
+	u.mailboxes = append(u.mailboxes, *mailbox)
+}
+
+func TestDeleteMessages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "qa@temp.mail", LocalPart: "qa", Domain: "temp.mail", CreatedAt: time.Now(),
+	}))
+	messages := service.NewMessageService(store)
+	updates := &mailboxUpdates{}
+	messages.SetMailboxUpdateNotifier(store, updates)
+	var ids []string
+	for _, subject := range []string{"one", "two", "three"} {
+		message, err := messages.Create(t.Context(), service.CreateMessageInput{MailboxID: "mb-1", From: "a@example.com", Subject: subject})
+		require.NoError(t, err)
+		ids = append(ids, message.ID)
+	}
+
+	h := &Handler{messages: messages}
+	router := gin.New()
+	router.DELETE("/v1/mailboxes/:id/messages", h.clearMessages)
+	router.DELETE("/v1/mailboxes/:id/messages/:messageId", h.deleteMessage)
+	del := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+		return w
+	}
+
+	t.Run("删除单封邮件并推送邮箱统计", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, del("/v1/mailboxes/mb-1/messages/"+ids[0]).Code)
+		require.Len(t, updates.mailboxes, 1)
+		assert.Equal(t, 2, updates.mailboxes[0].TotalCount)
+		assert.Equal(t, 2, updates.mailboxes[0].Unread)
+
+		assert.Equal(t, http.StatusNotFound, del("/v1/mailboxes/mb-1/messages/"+ids[0]).Code)
+		assert.Len(t, updates.mailboxes, 1)
+	})
+
+	t.Run("清空邮箱返回删除数量", func(t *testing.T) {
+		w := del("/v1/mailboxes/mb-1/messages")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data clearMessagesResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 2, resp.Data.Deleted)
+		require.Len(t, updates.mailboxes, 2)
+		assert.Zero(t, updates.mailboxes[1].TotalCount)
+
+		remaining, err := store.ListMessages(t.Context(), "mb-1")
+		require.NoError(t, err)
+		assert.Empty(t, remaining)
+
+		// 已经为空时不重复推送
+		assert.Equal(t, http.StatusOK, del("/v1/mailboxes/mb-1/messages").Code)
+		assert.Len(t, updates.mailboxes, 2)
+		assert.Equal(t, http.StatusNotFound, del("/v1/mailboxes/mb-missing/messages").Code)
+	})
+}
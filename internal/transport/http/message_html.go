@@ -0,0 +1,126 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/redact"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// getMessageHTML godoc
+// @Summary 渲染邮件 HTML 正文
+// @Description 以 text/html 返回邮件正文，供 iframe 直接展示。默认返回安全版本：移除脚本、表单、事件属性和跟踪像素，
+// @Description 远程图片按 TEMPMAIL_RENDER_REMOTE_IMAGES 拦截（默认）、代理或保留。sanitized=false 返回原文，
+// @Description 两种情况下响应都带禁止脚本的 Content-Security-Policy，远程图片同样按配置限制
+// @Tags Messages
+// @Produce html
+// @Param id path string true "邮箱ID"
+// @Param messageId path string true "邮件ID"
+// @Param sanitized query bool false "是否返回安全版本（默认 true）"
+// @Success 200 {string} string "HTML 文档；X-Remote-Images、X-Trackers-Removed 头为拦截或代理的远程图片数和移除的跟踪像素数"
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/mailboxes/{id}/messages/{messageId}/html [get]
+func (h *Handler) getMessageHTML(c *gin.Context) {
+	sanitized := true
+	if raw := c.Query("sanitized"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+		sanitized = value
+	}
+
+	var document string
+	if sanitized {
+		result, err := h.messages.SafeHTML(c.Request.Context(), c.Param("id"), c.Param("messageId"))
+		if err != nil {
+			respondMessageHTMLError(c, err)
+			return
+		}
+		c.Header("X-Remote-Images", strconv.Itoa(result.RemoteImages))
+		c.Header("X-Trackers-Removed", strconv.Itoa(result.TrackersRemoved))
+		document = `<!DOCTYPE html><html><head><meta charset="utf-8"></head><body>` + result.HTML + `</body></html>`
+	} else {
+		body, err := h.messages.HTML(c.Request.Context(), c.Param("id"), c.Param("messageId"))
+		if err != nil {
+			respondMessageHTMLError(c, err)
+			return
+		}
+		document = body
+	}
+
+	c.Header("Content-Security-Policy", renderCSP(h.messages.RemoteImages()))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(document))
+}
+
+// renderCSP 邮件正文的内容安全策略：禁止脚本、表单和外部样式，图片来源按远程图片配置
+func renderCSP(remoteImages string) string {
+	imgSrc := "data:"
+	switch remoteImages {
+	case redact.RemoteImagesAllow:
+		imgSrc = "http: https: data:"
+	case redact.RemoteImagesProxy:
+		imgSrc = "'self' data:"
+	}
+	return "default-src 'none'; style-src 'unsafe-inline'; font-src data:; base-uri 'none'; form-action 'none'; img-src " + imgSrc
+}
+
+func respondMessageHTMLError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, memory.ErrMessageNotFound):
+		NotFound(c, MsgMessageNotFound)
+	case errors.Is(err, service.ErrMessageHTMLUnavailable):
+		NotFound(c, GetErrorMessage(err))
+	default:
+		InternalError(c, MsgInternalError)
+	}
+}
+
+// ImageProxyHandler 邮件远程图片代理处理器
+type ImageProxyHandler struct {
+	proxy *service.ImageProxy
+}
+
+// NewImageProxyHandler 创建图片代理处理器
+func NewImageProxyHandler(proxy *service.ImageProxy) *ImageProxyHandler {
+	return &ImageProxyHandler{proxy: proxy}
+}
+
+// Fetch godoc
+// @Summary 代理加载邮件中的远程图片
+// @Description 安全渲染的邮件正文中远程图片改写为此地址（TEMPMAIL_RENDER_REMOTE_IMAGES=proxy），地址带签名，不能代理其他地址。
+// @Description 只加载公网地址的 PNG、JPEG、GIF、WebP、AVIF、BMP 和图标，不转发收件人的 IP、Cookie 和 Referer
+// @Tags Messages
+// @Produce image/png
+// @Param url query string true "远程图片地址"
+// @Param sig query string true "签名"
+// @Success 200 {file} binary
+// @Failure 403 {object} Response
+// @Failure 502 {object} Response
+// @Router /v1/image-proxy [get]
+func (h *ImageProxyHandler) Fetch(c *gin.Context) {
+	image, err := h.proxy.Fetch(c.Request.Context(), c.Query("url"), c.Query("sig"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrImageProxySignature):
+			Forbidden(c, GetErrorMessage(err))
+		default:
+			Error(c, http.StatusBadGateway, GetErrorMessage(err))
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Header("Content-Security-Policy", "default-src 'none'")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, image.ContentType, image.Data)
+}
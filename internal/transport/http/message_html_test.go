@@ -0,0 +1,68 @@
+package httptransport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/redact"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestGetMessageHTML(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "qa@temp.mail", LocalPart: "qa", Domain: "temp.mail", CreatedAt: time.Now(),
+	}))
+	messages := service.NewMessageService(store)
+	withHTML, err := messages.Create(t.Context(), service.CreateMessageInput{
+		MailboxID: "mb-1", From: "a@example.com", Subject: "html",
+		HTML: `<p onclick="x()">Hello</p><img src="https://cdn.example/a.png"><img src="https://t.example/p.gif" width="1">`,
+	})
+	require.NoError(t, err)
+	textOnly, err := messages.Create(t.Context(), service.CreateMessageInput{MailboxID: "mb-1", From: "a@example.com", Text: "plain"})
+	require.NoError(t, err)
+
+	h := &Handler{messages: messages}
+	router := gin.New()
+	router.GET("/v1/mailboxes/:id/messages/:messageId/html", h.getMessageHTML)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("默认返回安全版本", func(t *testing.T) {
+		w := get("/v1/mailboxes/mb-1/messages/" + withHTML.ID + "/html")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Security-Policy"), "img-src data:")
+		assert.Equal(t, "1", w.Header().Get("X-Remote-Images"))
+		assert.Equal(t, "1", w.Header().Get("X-Trackers-Removed"))
+		assert.Contains(t, w.Body.String(), "<body><p>Hello</p><img/></body>")
+	})
+
+	t.Run("返回原文时仍带内容安全策略", func(t *testing.T) {
+		messages.SetSafeHTMLOptions(redact.SafeHTMLOptions{RemoteImages: redact.RemoteImagesAllow})
+		w := get("/v1/mailboxes/mb-1/messages/" + withHTML.ID + "/html?sanitized=false")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `onclick="x()"`)
+		assert.Contains(t, w.Header().Get("Content-Security-Policy"), "default-src 'none'")
+		assert.Contains(t, w.Header().Get("Content-Security-Policy"), "img-src http: https: data:")
+	})
+
+	t.Run("参数和不存在的正文", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("/v1/mailboxes/mb-1/messages/"+withHTML.ID+"/html?sanitized=maybe").Code)
+		assert.Equal(t, http.StatusNotFound, get("/v1/mailboxes/mb-1/messages/"+textOnly.ID+"/html").Code)
+		assert.Equal(t, http.StatusNotFound, get("/v1/mailboxes/mb-1/messages/missing/html").Code)
+	})
+}
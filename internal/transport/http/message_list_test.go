@@ -0,0 +1,88 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestListMessagesPagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "qa@temp.mail", LocalPart: "qa", Domain: "temp.mail", CreatedAt: time.Now(),
+	}))
+	messages := service.NewMessageService(store)
+	base := time.Now().Add(-time.Hour)
+	ids := make(map[string]string)
+	for i, subject := range []string{"banana", "Apple", "cherry", "date"} {
+		message, err := messages.Create(t.Context(), service.CreateMessageInput{
+			MailboxID: "mb-1", From: "sender@example.com", Subject: subject, Received: base.Add(time.Duration(i) * time.Minute),
+		})
+		require.NoError(t, err)
+		ids[subject] = message.ID
+	}
+	require.NoError(t, messages.MarkRead(t.Context(), "mb-1", ids["cherry"]))
+
+	h := &Handler{messages: messages}
+	router := gin.New()
+	router.GET("/v1/mailboxes/:id/messages", h.listMessages)
+	list := func(query string) (int, messageListResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/mailboxes/mb-1/messages?"+query, nil))
+		var resp struct {
+			Data messageListResponse `json:"data"`
+		}
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w.Code, resp.Data
+	}
+	subjects := func(resp messageListResponse) []string {
+		result := make([]string, 0, len(resp.Items))
+		for _, item := range resp.Items {
+			result = append(result, item.Subject)
+		}
+		return result
+	}
+
+	t.Run("默认按接收时间倒序分页", func(t *testing.T) {
+		code, resp := list("limit=3")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"date", "cherry", "Apple"}, subjects(resp))
+		assert.Equal(t, 4, resp.Total)
+		assert.True(t, resp.HasMore)
+
+		code, resp = list("limit=3&offset=3")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"banana"}, subjects(resp))
+		assert.Equal(t, 3, resp.Offset)
+		assert.False(t, resp.HasMore)
+	})
+
+	t.Run("按主题排序并只看未读", func(t *testing.T) {
+		_, resp := list("sort=subject&order=asc")
+		assert.Equal(t, []string{"Apple", "banana", "cherry", "date"}, subjects(resp))
+		_, resp = list("sort=subject&unreadOnly=true")
+		assert.Equal(t, []string{"date", "banana", "Apple"}, subjects(resp))
+		assert.Equal(t, 3, resp.Total)
+	})
+
+	t.Run("无效参数返回 400", func(t *testing.T) {
+		for _, query := range []string{"sort=size", "order=up", "limit=-1", "offset=x", "unreadOnly=maybe"} {
+			code, _ := list(query)
+			assert.Equal(t, http.StatusBadRequest, code, query)
+		}
+	})
+}
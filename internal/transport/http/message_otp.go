@@ -0,0 +1,74 @@
+package httptransport
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// getMessageOTP godoc
+// @Summary 获取邮件中的验证码
+// @Description 返回入库时从正文提取的一次性验证码（code 为最可能的一个，codes 为全部候选）和验证、激活、登录链接；都没有时字段为空
+// @Tags Messages
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param messageId path string true "邮件ID"
+// @Success 200 {object} Response{data=service.MessageOTP}
+// @Failure 404 {object} Response
+// @Router /v1/mailboxes/{id}/messages/{messageId}/otp [get]
+func (h *Handler) getMessageOTP(c *gin.Context) {
+	result, err := h.messages.OTP(c.Request.Context(), c.Param("id"), c.Param("messageId"))
+	if err != nil {
+		if errors.Is(err, memory.ErrMessageNotFound) {
+			NotFound(c, MsgMessageNotFound)
+			return
+		}
+		InternalError(c, MsgInternalError)
+		return
+	}
+
+	Success(c, result)
+}
+
+// getLatestOTP godoc
+// @Summary 获取最新的验证码
+// @Description 返回邮箱中最新一封带验证码或验证链接的邮件的提取结果（不含隔离区），用于 CI 中轮询验证码。
+// @Description since 为 RFC 3339 时间，只查找此后接收的邮件；没有找到时返回 404
+// @Tags Messages
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param since query string false "只查找此时间之后接收的邮件（RFC 3339）"
+// @Success 200 {object} Response{data=service.MessageOTP}
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/mailboxes/{id}/otp [get]
+func (h *Handler) getLatestOTP(c *gin.Context) {
+	var since time.Time
+	if raw := c.Query("since"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+		since = t
+	}
+
+	result, err := h.messages.LatestOTP(c.Request.Context(), c.Param("id"), since)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrOTPNotFound):
+			NotFound(c, GetErrorMessage(err))
+		case errors.Is(err, memory.ErrMailboxNotFound):
+			NotFound(c, MsgMailboxNotFound)
+		default:
+			InternalError(c, MsgInternalError)
+		}
+		return
+	}
+
+	Success(c, result)
+}
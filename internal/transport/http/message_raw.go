@@ -0,0 +1,44 @@
+package httptransport
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// downloadMessageRaw godoc
+// @Summary 下载原始邮件
+// @Description 以 .eml 附件形式返回邮件入库时保存的原始内容（未经改写的 RFC 822），可交给 MIME 解析器或用于滥用举报
+// @Tags Messages
+// @Produce message/rfc822
+// @Param id path string true "邮箱ID"
+// @Param messageId path string true "邮件ID"
+// @Success 200 {file} binary
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /v1/mailboxes/{id}/messages/{messageId}/raw [get]
+func (h *Handler) downloadMessageRaw(c *gin.Context) {
+	messageID := c.Param("messageId")
+	raw, err := h.messages.GetRaw(c.Request.Context(), c.Param("id"), messageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, memory.ErrMessageNotFound):
+			NotFound(c, MsgMessageNotFound)
+		case errors.Is(err, service.ErrMessageRawUnavailable):
+			NotFound(c, GetErrorMessage(service.ErrMessageRawUnavailable))
+		default:
+			InternalError(c, MsgInternalError)
+		}
+		return
+	}
+
+	// 原样返回，不使用统一响应格式
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", messageID+".eml"))
+	c.Data(http.StatusOK, "message/rfc822", raw)
+}
@@ -0,0 +1,111 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+)
+
+// sendMessageRequest 发信请求
+type sendMessageRequest struct {
+	To      []string `json:"to,omitempty"` // 回复时可省略，默认回复原发件人（Reply-To 优先）
+	Cc      []string `json:"cc,omitempty"`
+	Subject string   `json:"subject,omitempty"` // 回复时可省略，默认 "Re: 原主题"
+	Text    string   `json:"text,omitempty"`
+	HTML    string   `json:"html,omitempty"`
+	// ReplyTo 回复的收件邮件 ID（设置 In-Reply-To/References）
+	ReplyTo string `json:"replyTo,omitempty"`
+}
+
+// sendMessage godoc
+// @Summary 发送邮件（回复）
+// @Description 以邮箱地址为发件人经配置的 SMTP 中继发出邮件，并记录到已发送文件夹。受每个邮箱和每个用户的 24 小时发信配额限制，默认不允许游客邮箱发信
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param request body sendMessageRequest true "收件人和正文"
+// @Success 201 {object} Response{data=domain.SentMessage}
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
+// @Failure 502 {object} Response
+// @Failure 503 {object} Response
+// @Router /v1/mailboxes/{id}/messages/send [post]
+func (h *Handler) sendMessage(c *gin.Context) {
+	var req sendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	mailbox, _ := c.Get("mailbox")
+
+	sent, err := h.messages.Send(c.Request.Context(), mailbox.(*domain.Mailbox), service.SendMessageInput{
+		To:               req.To,
+		Cc:               req.Cc,
+		Subject:          req.Subject,
+		Text:             req.Text,
+		HTML:             req.HTML,
+		ReplyToMessageID: req.ReplyTo,
+	})
+	if err != nil {
+		var quotaErr *service.SendQuotaError
+		switch {
+		case errors.As(err, &quotaErr):
+			message := MsgSendQuotaMailbox
+			if quotaErr.Scope == service.SendQuotaUser {
+				message = MsgSendQuotaUser
+			}
+			middleware.RespondThrottled(c, middleware.Throttle{
+				Status:     http.StatusTooManyRequests,
+				ErrorCode:  middleware.ErrorCodeQuotaExceeded,
+				Message:    message,
+				RetryAfter: time.Until(quotaErr.Reset),
+				Window:     &middleware.QuotaWindow{Limit: quotaErr.Limit, Remaining: 0, Reset: quotaErr.Reset},
+				Data:       gin.H{"scope": quotaErr.Scope},
+			})
+		case errors.Is(err, service.ErrSendRecipientsInvalid),
+			errors.Is(err, service.ErrSendTooManyRecipients),
+			errors.Is(err, service.ErrSendBodyEmpty),
+			errors.Is(err, service.ErrSendBodyTooLarge):
+			BadRequest(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrSendRequiresAccount):
+			Forbidden(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrSendReplyNotFound):
+			NotFound(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrSendingDisabled):
+			Error(c, http.StatusServiceUnavailable, GetErrorMessage(err))
+		case errors.Is(err, service.ErrSendRelayFailed):
+			Error(c, http.StatusBadGateway, GetErrorMessage(service.ErrSendRelayFailed))
+		default:
+			InternalError(c, MsgSendMessageFailed)
+		}
+		return
+	}
+
+	Created(c, sent)
+}
+
+// listSentMessages godoc
+// @Summary 已发送邮件列表
+// @Description 列出邮箱经中继发出的邮件（新邮件在前）
+// @Tags Messages
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Success 200 {object} Response{data=[]domain.SentMessage}
+// @Router /v1/mailboxes/{id}/sent [get]
+func (h *Handler) listSentMessages(c *gin.Context) {
+	sent, err := h.messages.ListSent(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		InternalError(c, MsgInternalError)
+		return
+	}
+	Success(c, sent)
+}
@@ -0,0 +1,84 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+type discardSender struct{}
+
+func (discardSender) Send(context.Context, string, []string, []byte) error { return nil }
+
+func TestSendMessage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	userID := "user-1"
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "team@temp.mail", LocalPart: "team", Domain: "temp.mail", Token: "secret-token",
+		UserID: &userID, CreatedAt: time.Now(),
+	}))
+	mailboxes := service.NewMailboxService(store, store, &config.Config{})
+	messages := service.NewMessageService(store)
+	h := &Handler{messages: messages}
+	mailboxAuth := middleware.NewMailboxAuth(mailboxes)
+	router := gin.New()
+	router.POST("/v1/mailboxes/:id/messages/send", mailboxAuth.RequireMailboxToken(), h.sendMessage)
+	router.GET("/v1/mailboxes/:id/sent", mailboxAuth.RequireMailboxToken(), h.listSentMessages)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/mailboxes/mb-1/messages/send", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Mailbox-Token", "secret-token")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	body := `{"to":["a@example.com"],"subject":"hi","text":"hello"}`
+
+	t.Run("未配置中继时返回 503", func(t *testing.T) {
+		assert.Equal(t, http.StatusServiceUnavailable, send(body).Code)
+	})
+
+	messages.SetOutbound(discardSender{}, store, service.SendOptions{MailboxDailyLimit: 1})
+
+	t.Run("发信并出现在已发送列表", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send(`{"to":["nope"],"text":"hello"}`).Code)
+		w := send(body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/mailboxes/mb-1/sent", nil)
+		req.Header.Set("X-Mailbox-Token", "secret-token")
+		router.ServeHTTP(w, req)
+		var resp struct {
+			Data []domain.SentMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Data, 1)
+		assert.Equal(t, "hi", resp.Data[0].Subject)
+	})
+
+	t.Run("配额用尽返回 429", func(t *testing.T) {
+		w := send(body)
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+		assert.Contains(t, w.Body.String(), middleware.ErrorCodeQuotaExceeded)
+	})
+}
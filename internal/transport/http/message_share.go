@@ -0,0 +1,230 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+)
+
+// 分享链接的公开访问路径前缀
+const (
+	sharedMessagePath = "/v1/shared/messages/"
+	sharedMailboxPath = "/v1/shared/mailboxes/"
+)
+
+// createMessageShareRequest 创建分享链接请求
+type createMessageShareRequest struct {
+	ExpiresIn        string `json:"expiresIn,omitempty"` // 有效期，如 "2h"、"72h"（默认 24h，最长 168h）
+	AllowAttachments bool   `json:"allowAttachments"`
+	Redacted         bool   `json:"redacted"` // 分享脱敏副本（需先生成脱敏副本）
+}
+
+// messageShareResponse 分享记录及访问地址（撤销或过期后不再返回令牌和地址）
+type messageShareResponse struct {
+	service.MessageShareLink
+	URL string `json:"url,omitempty"`
+}
+
+func newMessageShareResponse(link service.MessageShareLink) messageShareResponse {
+	resp := messageShareResponse{MessageShareLink: link}
+	switch {
+	case link.Token == "":
+	case link.MailboxWide():
+		resp.URL = sharedMailboxPath + link.Token
+	default:
+		resp.URL = sharedMessagePath + link.Token
+	}
+	return resp
+}
+
+// createMessageShare godoc
+// @Summary 创建邮件分享链接
+// @Description 为单封邮件生成限时只读链接（签名令牌），无需邮箱令牌即可查看。可选允许下载附件、分享脱敏副本；隔离区中的邮件不能分享
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param messageId path string true "邮件ID"
+// @Param request body createMessageShareRequest false "有效期和选项"
+// @Success 201 {object} Response{data=messageShareResponse}
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/mailboxes/{id}/messages/{messageId}/share [post]
+func (h *Handler) createMessageShare(c *gin.Context) {
+	var req createMessageShareRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+	}
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		duration, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil {
+			BadRequest(c, GetErrorMessage(service.ErrShareExpiryInvalid))
+			return
+		}
+		ttl = duration
+	}
+
+	link, err := h.shares.Create(c.Request.Context(), c.Param("id"), c.Param("messageId"), service.CreateShareInput{
+		TTL:              ttl,
+		AllowAttachments: req.AllowAttachments,
+		Redacted:         req.Redacted,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrShareExpiryInvalid):
+			BadRequest(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrShareMessageNotFound):
+			NotFound(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrShareNotAllowed), errors.Is(err, service.ErrShareRedactionMissing):
+			Conflict(c, GetErrorMessage(err))
+		default:
+			InternalError(c, MsgMessageShareFailed)
+		}
+		return
+	}
+
+	Created(c, newMessageShareResponse(*link))
+}
+
+// listMessageShares godoc
+// @Summary 分享链接列表
+// @Description 列出邮箱创建的分享链接（单封邮件和整个邮箱）及访问次数（新链接在前），仍有效的链接返回访问地址；整个邮箱的分享没有 messageId
+// @Tags Messages
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Success 200 {object} Response{data=[]messageShareResponse}
+// @Router /v1/mailboxes/{id}/shares [get]
+func (h *Handler) listMessageShares(c *gin.Context) {
+	links, err := h.shares.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		InternalError(c, MsgInternalError)
+		return
+	}
+
+	items := make([]messageShareResponse, 0, len(links))
+	for _, link := range links {
+		items = append(items, newMessageShareResponse(link))
+	}
+	Success(c, items)
+}
+
+// revokeMessageShare godoc
+// @Summary 撤销分享链接
+// @Description 撤销后链接立即失效（返回 410），其他链接不受影响
+// @Tags Messages
+// @Param id path string true "邮箱ID"
+// @Param shareId path string true "分享ID"
+// @Success 204
+// @Failure 404 {object} Response
+// @Router /v1/mailboxes/{id}/shares/{shareId} [delete]
+func (h *Handler) revokeMessageShare(c *gin.Context) {
+	if _, err := h.shares.Revoke(c.Request.Context(), c.Param("id"), c.Param("shareId")); err != nil {
+		if errors.Is(err, service.ErrShareNotFound) {
+			NotFound(c, GetErrorMessage(err))
+			return
+		}
+		InternalError(c, MsgInternalError)
+		return
+	}
+
+	NoContent(c)
+}
+
+// SharedMessageHandler 分享链接的公开访问处理器（凭签名令牌访问，无需认证）
+type SharedMessageHandler struct {
+	shares *service.MessageShareService
+}
+
+// NewSharedMessageHandler 创建分享链接访问处理器
+func NewSharedMessageHandler(shares *service.MessageShareService) *SharedMessageHandler {
+	return &SharedMessageHandler{shares: shares}
+}
+
+// GetMessage godoc
+// @Summary 查看分享的邮件
+// @Description 凭分享链接查看单封邮件（HTML 已清理，不含邮箱的其他信息），每次查看计入访问次数
+// @Tags Public
+// @Produce json
+// @Param token path string true "分享令牌"
+// @Success 200 {object} Response{data=service.SharedMessage}
+// @Failure 404 {object} Response
+// @Failure 410 {object} Response
+// @Failure 429 {object} Response
+// @Router /v1/shared/messages/{token} [get]
+func (h *SharedMessageHandler) GetMessage(c *gin.Context) {
+	noStore(c)
+	message, err := h.shares.Open(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		if !h.respondError(c, err) {
+			InternalError(c, MsgMessageGetFailed)
+		}
+		return
+	}
+
+	Success(c, message)
+}
+
+// DownloadAttachment godoc
+// @Summary 下载分享邮件的附件
+// @Description 仅创建链接时允许下载附件才可用
+// @Tags Public
+// @Produce application/octet-stream
+// @Param token path string true "分享令牌"
+// @Param attachmentId path string true "附件ID"
+// @Success 200 {file} binary
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 410 {object} Response
+// @Router /v1/shared/messages/{token}/attachments/{attachmentId} [get]
+func (h *SharedMessageHandler) DownloadAttachment(c *gin.Context) {
+	noStore(c)
+	attachment, err := h.shares.OpenAttachment(c.Request.Context(), c.Param("token"), c.Param("attachmentId"))
+	if err != nil {
+		if !h.respondError(c, err) {
+			NotFound(c, MsgAttachmentNotFound)
+		}
+		return
+	}
+
+	content, err := attachment.Open()
+	if err != nil {
+		InternalError(c, MsgAttachmentNotFound)
+		return
+	}
+	defer content.Close()
+
+	serveAttachment(c, attachment, content)
+}
+
+// respondError 响应分享链接的业务错误，不是业务错误时返回 false
+func (h *SharedMessageHandler) respondError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrShareLinkInvalid):
+		NotFound(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrShareLinkExpired):
+		Error(c, http.StatusGone, GetErrorMessage(err))
+	case errors.Is(err, service.ErrShareAttachmentsNotAllowed):
+		Forbidden(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrShareMessageNotFound):
+		NotFound(c, GetErrorMessage(err))
+	default:
+		return false
+	}
+	return true
+}
+
+// noStore 分享内容只给持有链接的人看：禁止共享缓存保存，也不通过 Referer 泄露令牌
+func noStore(c *gin.Context) {
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("X-Robots-Tag", "noindex")
+}
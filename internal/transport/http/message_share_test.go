@@ -0,0 +1,159 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestMessageShare(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "team@temp.mail", LocalPart: "team", Domain: "temp.mail", Token: "secret-token",
+		CreatedAt: time.Now(),
+	}))
+	mailboxes := service.NewMailboxService(store, store, &config.Config{})
+	messages := service.NewMessageService(store)
+	msg, err := messages.Create(t.Context(), service.CreateMessageInput{
+		MailboxID: "mb-1", From: "a@example.com", To: "team@temp.mail", Subject: "quarterly numbers", Text: "see attached",
+		HTML:        `<p>see attached</p><script>steal()</script>`,
+		Attachments: []*domain.Attachment{{ID: "att-1", Filename: "q3.csv", ContentType: "text/csv", Size: 5, Content: []byte("a,b,c")}},
+	})
+	require.NoError(t, err)
+
+	shares := service.NewMessageShareService(store, messages, jwtpkg.NewManager(strings.Repeat("s", 32), "tempmail", time.Hour, time.Hour))
+	h := &Handler{shares: shares}
+	shared := NewSharedMessageHandler(shares)
+	mailboxAuth := middleware.NewMailboxAuth(mailboxes)
+	router := gin.New()
+	router.POST("/v1/mailboxes/:id/messages/:messageId/share", mailboxAuth.RequireMailboxToken(), h.createMessageShare)
+	router.GET("/v1/mailboxes/:id/shares", mailboxAuth.RequireMailboxToken(), h.listMessageShares)
+	router.DELETE("/v1/mailboxes/:id/shares/:shareId", mailboxAuth.RequireMailboxToken(), h.revokeMessageShare)
+	router.GET("/v1/shared/messages/:token", shared.GetMessage)
+	router.GET("/v1/shared/messages/:token/attachments/:attachmentId", shared.DownloadAttachment)
+	router.POST("/v1/mailboxes/:id/shares", mailboxAuth.RequireMailboxToken(), h.createMailboxShare)
+	router.GET("/v1/shared/mailboxes/:token", shared.GetMailbox)
+	router.GET("/v1/shared/mailboxes/:token/messages/:messageId", shared.GetMailboxMessage)
+	router.GET("/v1/shared/mailboxes/:token/messages/:messageId/attachments/:attachmentId", shared.DownloadMailboxAttachment)
+
+	do := func(method, path, body string, authed bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if authed {
+			req.Header.Set("X-Mailbox-Token", "secret-token")
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(t *testing.T, body string) messageShareResponse {
+		t.Helper()
+		w := do(http.MethodPost, "/v1/mailboxes/mb-1/messages/"+msg.ID+"/share", body, true)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var resp struct {
+			Data messageShareResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.True(t, strings.HasPrefix(resp.Data.URL, sharedMessagePath))
+		return resp.Data
+	}
+
+	t.Run("创建链接需要邮箱令牌并校验有效期", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/v1/mailboxes/mb-1/messages/"+msg.ID+"/share", "", false).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/v1/mailboxes/mb-1/messages/"+msg.ID+"/share", `{"expiresIn":"200h"}`, true).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/v1/mailboxes/mb-1/messages/"+msg.ID+"/share", `{"expiresIn":"soon"}`, true).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/v1/mailboxes/mb-1/messages/missing/share", "", true).Code)
+		assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/v1/mailboxes/mb-1/messages/"+msg.ID+"/share", `{"redacted":true}`, true).Code)
+	})
+
+	t.Run("凭链接查看邮件", func(t *testing.T) {
+		link := create(t, `{"expiresIn":"2h"}`)
+		w := do(http.MethodGet, link.URL, "", false)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "private, no-store", w.Header().Get("Cache-Control"))
+		assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+		assert.Contains(t, w.Body.String(), "quarterly numbers")
+		assert.NotContains(t, w.Body.String(), "<script>")
+		assert.NotContains(t, w.Body.String(), "team@temp.mail", "不暴露邮箱地址")
+		assert.NotContains(t, w.Body.String(), "q3.csv", "未允许附件时不列出")
+
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, link.URL+"/attachments/att-1", "", false).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, link.URL+"x", "", false).Code, "签名被篡改")
+	})
+
+	t.Run("允许下载附件", func(t *testing.T) {
+		link := create(t, `{"allowAttachments":true}`)
+		w := do(http.MethodGet, link.URL+"/attachments/att-1", "", false)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "a,b,c", w.Body.String())
+		assert.Equal(t, "private, no-store", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("撤销后返回 410，列表显示访问次数", func(t *testing.T) {
+		link := create(t, "")
+		require.Equal(t, http.StatusOK, do(http.MethodGet, link.URL, "", false).Code)
+
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/v1/mailboxes/mb-1/shares/"+link.ID, "", true).Code)
+		assert.Equal(t, http.StatusGone, do(http.MethodGet, link.URL, "", false).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/v1/mailboxes/mb-1/shares/missing", "", true).Code)
+
+		w := do(http.MethodGet, "/v1/mailboxes/mb-1/shares", "", true)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []messageShareResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Data, 3)
+		revoked := resp.Data[0]
+		assert.Equal(t, link.ID, revoked.ID)
+		assert.Equal(t, int64(1), revoked.Views)
+		assert.Empty(t, revoked.URL, "撤销后不再返回地址")
+		assert.NotContains(t, w.Body.String(), "nonce")
+	})
+	t.Run("分享整个邮箱", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/v1/mailboxes/mb-1/shares", "", false).Code)
+		w := do(http.MethodPost, "/v1/mailboxes/mb-1/shares", `{"expiresIn":"1h","allowAttachments":true}`, true)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var resp struct {
+			Data messageShareResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.True(t, strings.HasPrefix(resp.Data.URL, sharedMailboxPath))
+		assert.NotContains(t, w.Body.String(), "secret-token")
+
+		w = do(http.MethodGet, resp.Data.URL, "", false)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "private, no-store", w.Header().Get("Cache-Control"))
+		assert.Contains(t, w.Body.String(), "quarterly numbers")
+		assert.NotContains(t, w.Body.String(), "secret-token")
+
+		w = do(http.MethodGet, resp.Data.URL+"/messages/"+msg.ID, "", false)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "<script>")
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, resp.Data.URL+"/messages/missing", "", false).Code)
+		w = do(http.MethodGet, resp.Data.URL+"/messages/"+msg.ID+"/attachments/att-1", "", false)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "a,b,c", w.Body.String())
+
+		token := strings.TrimPrefix(resp.Data.URL, sharedMailboxPath)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, sharedMessagePath+token, "", false).Code, "邮箱链接不能当作邮件链接")
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/v1/mailboxes/mb-1/shares/"+resp.Data.ID, "", true).Code)
+		assert.Equal(t, http.StatusGone, do(http.MethodGet, resp.Data.URL, "", false).Code)
+	})
+}
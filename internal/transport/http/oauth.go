@@ -0,0 +1,275 @@
+package httptransport
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+
+	"tempmail/backend/internal/auth"
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/config"
+)
+
+const (
+	// oauthFlowCookie 保存 state、nonce 和 PKCE verifier 的 Cookie（只发送到 /v1/auth/oauth）
+	oauthFlowCookie     = "oauth_flow"
+	oauthFlowCookiePath = "/v1/auth/oauth"
+	oauthFlowMaxAge     = 10 * 60 // 秒
+)
+
+// 回调失败时跳转到前端的错误代码（URL 片段中的 error 参数）
+const (
+	oauthErrorInvalidState     = "invalid_state"
+	oauthErrorEmailUnverified  = "email_unverified"
+	oauthErrorUserInactive     = "user_inactive"
+	oauthErrorLoginFailed      = "login_failed"
+	oauthErrorProviderNotFound = "provider_not_found"
+)
+
+// oauthFlow 一次授权流程的校验参数
+type oauthFlow struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+}
+
+// oauthProvidersResponse 已配置的第三方登录方式
+type oauthProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+// OAuthHandler 第三方登录（OAuth2/OIDC）处理器
+type OAuthHandler struct {
+	oauth        *auth.OAuthService
+	jwtManager   *jwtpkg.Manager
+	sessions     *auth.SessionService // 可选：登录时创建会话
+	frontendURL  string               // 登录完成后跳转的前端地址，为空时回调返回 JSON
+	secureCookie bool                 // 回调地址为 HTTPS 时流程 Cookie 设置 Secure
+	log          *zap.Logger
+}
+
+// NewOAuthHandler 创建第三方登录处理器
+func NewOAuthHandler(oauthService *auth.OAuthService, jwtManager *jwtpkg.Manager, cfg config.OAuthConfig, log *zap.Logger) *OAuthHandler {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	return &OAuthHandler{
+		oauth:        oauthService,
+		jwtManager:   jwtManager,
+		frontendURL:  cfg.FrontendURL,
+		secureCookie: strings.HasPrefix(cfg.CallbackBaseURL, "https://"),
+		log:          log,
+	}
+}
+
+// Providers godoc
+// @Summary 获取第三方登录方式
+// @Description 列出已配置的第三方登录提供方名称，用于 /v1/auth/oauth/{provider}/start
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} Response{data=oauthProvidersResponse}
+// @Router /v1/auth/oauth/providers [get]
+func (h *OAuthHandler) Providers(c *gin.Context) {
+	Success(c, oauthProvidersResponse{Providers: h.oauth.Providers()})
+}
+
+// Start godoc
+// @Summary 发起第三方登录
+// @Description 生成 state、nonce 和 PKCE 参数（保存在 HttpOnly Cookie 中，10 分钟有效）并跳转到提供方的授权页
+// @Tags 认证
+// @Param provider path string true "提供方名称（google、github 或自定义 OIDC 名称）"
+// @Success 302 "跳转到提供方授权页"
+// @Failure 404 {object} Response "未配置该提供方"
+// @Failure 500 {object} Response "提供方不可用"
+// @Router /v1/auth/oauth/{provider}/start [get]
+func (h *OAuthHandler) Start(c *gin.Context) {
+	provider, err := h.oauth.Provider(c.Param("provider"))
+	if err != nil {
+		NotFound(c, MsgOAuthProviderNotFound)
+		return
+	}
+
+	flow := oauthFlow{
+		Provider: provider.Name(),
+		State:    randomToken(),
+		Nonce:    randomToken(),
+		Verifier: oauth2.GenerateVerifier(),
+	}
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), flow.State, flow.Nonce, flow.Verifier)
+	if err != nil {
+		h.log.Error("failed to build oauth authorization url", zap.String("provider", flow.Provider), zap.Error(err))
+		InternalError(c, MsgOAuthFailed)
+		return
+	}
+
+	value, _ := json.Marshal(flow)
+	h.setFlowCookie(c, base64.RawURLEncoding.EncodeToString(value), oauthFlowMaxAge)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback godoc
+// @Summary 第三方登录回调
+// @Description 校验 state 后用授权码换取身份：已关联的账号直接登录，否则按已验证的邮箱关联已有账户或自动注册。
+// @Description 配置了 oauth.frontend_url 时跳转到前端，令牌在 URL 片段中（#access_token=...&refresh_token=...&expires_in=...），失败时为 #error=...；否则返回 JSON。
+// @Description 已启用两步验证的账户返回质询令牌（片段中为 #two_factor_required=true&challenge_token=...），再调用 /v1/auth/2fa/login
+// @Tags 认证
+// @Produce json
+// @Param provider path string true "提供方名称"
+// @Param code query string true "授权码"
+// @Param state query string true "发起登录时生成的 state"
+// @Success 200 {object} Response{data=authResponse} "登录成功（未配置前端地址）"
+// @Success 302 "跳转到前端"
+// @Failure 400 {object} Response "state 无效或已过期"
+// @Failure 401 {object} Response "授权码换取失败"
+// @Failure 403 {object} Response "邮箱未验证或账户已被禁用"
+// @Failure 404 {object} Response "未配置该提供方"
+// @Router /v1/auth/oauth/{provider}/callback [get]
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	name := c.Param("provider")
+	provider, err := h.oauth.Provider(name)
+	if err != nil {
+		h.fail(c, http.StatusNotFound, oauthErrorProviderNotFound, MsgOAuthProviderNotFound)
+		return
+	}
+
+	flow, ok := h.takeFlow(c)
+	state := c.Query("state")
+	if !ok || flow.Provider != name || state == "" || subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		h.fail(c, http.StatusBadRequest, oauthErrorInvalidState, MsgOAuthStateInvalid)
+		return
+	}
+	if idpError := c.Query("error"); idpError != "" {
+		// 用户在提供方拒绝授权等
+		h.fail(c, http.StatusUnauthorized, idpError, MsgOAuthFailed)
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), flow.Nonce, flow.Verifier)
+	if err != nil {
+		h.log.Warn("oauth exchange failed", zap.String("provider", name), zap.Error(err))
+		h.fail(c, http.StatusUnauthorized, oauthErrorLoginFailed, MsgOAuthFailed)
+		return
+	}
+
+	user, err := h.oauth.Login(c.Request.Context(), identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrOAuthEmailUnverified):
+			h.fail(c, http.StatusForbidden, oauthErrorEmailUnverified, MsgOAuthEmailUnverified)
+		case errors.Is(err, auth.ErrUserInactive):
+			h.fail(c, http.StatusForbidden, oauthErrorUserInactive, "账户已被禁用")
+		default:
+			h.log.Error("oauth login failed", zap.String("provider", name), zap.Error(err))
+			h.fail(c, http.StatusInternalServerError, oauthErrorLoginFailed, MsgOAuthFailed)
+		}
+		return
+	}
+
+	// 启用了两步验证的账户第三方登录同样只算第一步
+	if user.TOTPEnabled {
+		h.twoFactorChallenge(c, user.ID)
+		return
+	}
+
+	tokens, err := issueTokens(c, h.sessions, h.jwtManager, user)
+	if err != nil {
+		h.log.Error("failed to generate tokens", zap.Error(err))
+		h.fail(c, http.StatusInternalServerError, oauthErrorLoginFailed, "生成令牌失败")
+		return
+	}
+
+	h.log.Info("user logged in via oauth",
+		zap.String("user_id", user.ID),
+		zap.String("provider", name),
+	)
+
+	if h.frontendURL != "" {
+		c.Redirect(http.StatusFound, h.frontendURL+"#"+url.Values{
+			"access_token":  {tokens.AccessToken},
+			"refresh_token": {tokens.RefreshToken},
+			"expires_in":    {strconv.FormatInt(tokens.ExpiresIn, 10)},
+			"token_type":    {"Bearer"},
+		}.Encode())
+		return
+	}
+	Success(c, authResponse{
+		User: userResponse{
+			ID:              user.ID,
+			Email:           user.Email,
+			Username:        user.Username,
+			Tier:            string(user.Tier),
+			IsActive:        user.IsActive,
+			IsEmailVerified: user.IsEmailVerified,
+		},
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	})
+}
+
+// twoFactorChallenge 返回两步验证质询：跳转时质询令牌在 URL 片段中（#two_factor_required=true&challenge_token=...）
+func (h *OAuthHandler) twoFactorChallenge(c *gin.Context, userID string) {
+	challenge, err := newTwoFactorChallenge(h.jwtManager, userID)
+	if err != nil {
+		h.log.Error("failed to generate two-factor challenge", zap.Error(err))
+		h.fail(c, http.StatusInternalServerError, oauthErrorLoginFailed, "生成令牌失败")
+		return
+	}
+	if h.frontendURL != "" {
+		c.Redirect(http.StatusFound, h.frontendURL+"#"+url.Values{
+			"two_factor_required": {"true"},
+			"challenge_token":     {challenge.ChallengeToken},
+			"expires_in":          {strconv.FormatInt(challenge.ExpiresIn, 10)},
+		}.Encode())
+		return
+	}
+	Success(c, challenge)
+}
+
+// takeFlow 读取并清除流程 Cookie（state 只能使用一次）
+func (h *OAuthHandler) takeFlow(c *gin.Context) (oauthFlow, bool) {
+	var flow oauthFlow
+	value, err := c.Cookie(oauthFlowCookie)
+	if err != nil {
+		return flow, false
+	}
+	h.setFlowCookie(c, "", -1)
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || json.Unmarshal(raw, &flow) != nil || flow.State == "" {
+		return flow, false
+	}
+	return flow, true
+}
+
+func (h *OAuthHandler) setFlowCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode) // 提供方跳转回来是跨站的顶层 GET，Strict 会丢失 Cookie
+	c.SetCookie(oauthFlowCookie, value, maxAge, oauthFlowCookiePath, "", h.secureCookie, true)
+}
+
+// fail 回调失败：配置了前端地址时带错误代码跳转，否则返回 JSON 错误
+func (h *OAuthHandler) fail(c *gin.Context, status int, code, msg string) {
+	if h.frontendURL != "" {
+		c.Redirect(http.StatusFound, h.frontendURL+"#"+url.Values{"error": {code}}.Encode())
+		return
+	}
+	Error(c, status, msg)
+}
+
+// randomToken 生成 32 字节随机值（base64url）
+func randomToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
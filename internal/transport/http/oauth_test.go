@@ -0,0 +1,165 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/auth"
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// stubOAuthProvider 测试用提供方：授权码 good 换取固定身份
+type stubOAuthProvider struct {
+	identity *auth.ExternalIdentity
+	nonce    string // 最近一次 Exchange 收到的 nonce
+}
+
+func (p *stubOAuthProvider) Name() string { return "stub" }
+
+func (p *stubOAuthProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	return "https://idp.example/authorize?" + url.Values{"state": {state}}.Encode(), nil
+}
+
+func (p *stubOAuthProvider) Exchange(ctx context.Context, code, nonce, verifier string) (*auth.ExternalIdentity, error) {
+	p.nonce = nonce
+	if code != "good" {
+		return nil, auth.ErrOAuthExchange
+	}
+	return p.identity, nil
+}
+
+func TestOAuthHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(time.Hour)
+	oauthService := auth.NewOAuthService(store, store)
+	provider := &stubOAuthProvider{identity: &auth.ExternalIdentity{
+		Provider: "stub", Subject: "s-1", Email: "oauth@example.com", EmailVerified: true, Name: "oauth",
+	}}
+	oauthService.Register(provider)
+	jwtManager := jwtpkg.NewManager("test-secret", "test", time.Hour, 24*time.Hour)
+
+	newRouter := func(cfg config.OAuthConfig) *gin.Engine {
+		h := NewOAuthHandler(oauthService, jwtManager, cfg, nil)
+		router := gin.New()
+		router.GET("/v1/auth/oauth/providers", h.Providers)
+		router.GET("/v1/auth/oauth/:provider/start", h.Start)
+		router.GET("/v1/auth/oauth/:provider/callback", h.Callback)
+		return router
+	}
+	router := newRouter(config.OAuthConfig{CallbackBaseURL: "https://api.example.com"})
+
+	// start 发起登录，返回授权地址中的 state 和流程 Cookie
+	start := func(t *testing.T, router *gin.Engine) (string, *http.Cookie) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/stub/start", nil))
+		require.Equal(t, http.StatusFound, w.Code)
+		location, err := url.Parse(w.Header().Get("Location"))
+		require.NoError(t, err)
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		return location.Query().Get("state"), cookies[0]
+	}
+	callback := func(router *gin.Engine, query url.Values, cookie *http.Cookie) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/stub/callback?"+query.Encode(), nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("列出已配置的提供方", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/providers", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"providers":["stub"]}`, string(mustData(t, w)))
+	})
+
+	t.Run("完整登录流程", func(t *testing.T) {
+		state, cookie := start(t, router)
+		assert.NotEmpty(t, state)
+		assert.Equal(t, oauthFlowCookie, cookie.Name)
+		assert.Equal(t, "/v1/auth/oauth", cookie.Path)
+		assert.True(t, cookie.HttpOnly)
+		assert.True(t, cookie.Secure, "回调地址为 HTTPS 时设置 Secure")
+		assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+		w := callback(router, url.Values{"code": {"good"}, "state": {state}}, cookie)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp authResponse
+		require.NoError(t, json.Unmarshal(mustData(t, w), &resp))
+		assert.Equal(t, "oauth@example.com", resp.User.Email)
+		assert.True(t, resp.User.IsEmailVerified)
+		claims, err := jwtManager.ValidateToken(resp.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, resp.User.ID, claims.UserID)
+		assert.NotEmpty(t, provider.nonce)
+
+		cleared := w.Result().Cookies()
+		require.Len(t, cleared, 1)
+		assert.Negative(t, cleared[0].MaxAge, "state 只能使用一次")
+	})
+
+	t.Run("state不匹配或缺少Cookie时拒绝", func(t *testing.T) {
+		_, cookie := start(t, router)
+		assert.Equal(t, http.StatusBadRequest, callback(router, url.Values{"code": {"good"}, "state": {"forged"}}, cookie).Code)
+
+		state, _ := start(t, router)
+		assert.Equal(t, http.StatusBadRequest, callback(router, url.Values{"code": {"good"}, "state": {state}}, nil).Code)
+	})
+
+	t.Run("授权码无效", func(t *testing.T) {
+		state, cookie := start(t, router)
+		assert.Equal(t, http.StatusUnauthorized, callback(router, url.Values{"code": {"bad"}, "state": {state}}, cookie).Code)
+	})
+
+	t.Run("未配置的提供方", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/unknown/start", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("配置前端地址时跳转并在片段中携带令牌", func(t *testing.T) {
+		router := newRouter(config.OAuthConfig{CallbackBaseURL: "http://localhost:8080", FrontendURL: "https://app.example.com/oauth"})
+		state, cookie := start(t, router)
+		assert.False(t, cookie.Secure)
+
+		w := callback(router, url.Values{"code": {"good"}, "state": {state}}, cookie)
+		require.Equal(t, http.StatusFound, w.Code)
+		location, err := url.Parse(w.Header().Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "https://app.example.com/oauth", location.Scheme+"://"+location.Host+location.Path)
+		fragment, err := url.ParseQuery(location.Fragment)
+		require.NoError(t, err)
+		assert.NotEmpty(t, fragment.Get("access_token"))
+		assert.NotEmpty(t, fragment.Get("refresh_token"))
+		assert.Equal(t, "Bearer", fragment.Get("token_type"))
+
+		w = callback(router, url.Values{"code": {"good"}, "state": {"forged"}}, cookie)
+		require.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://app.example.com/oauth#error=invalid_state", w.Header().Get("Location"))
+	})
+}
+
+// mustData 取出统一响应中的 data
+func mustData(t *testing.T, w *httptest.ResponseRecorder) json.RawMessage {
+	t.Helper()
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Data
+}
@@ -0,0 +1,256 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+)
+
+// OrgHandler 组织/团队处理器
+type OrgHandler struct {
+	service *service.OrgService
+}
+
+// NewOrgHandler 创建组织处理器
+func NewOrgHandler(service *service.OrgService) *OrgHandler {
+	return &OrgHandler{
+		service: service,
+	}
+}
+
+// CreateOrgRequest 创建组织请求
+type CreateOrgRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// InviteOrgMemberRequest 邀请成员请求
+type InviteOrgMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// JoinOrgRequest 加入组织请求
+type JoinOrgRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// orgQuotaRetryAfter 组织邮箱配额用尽时建议的重试间隔（配额按邮箱数计算，没有固定窗口，邮箱过期或删除后才会释放）
+const orgQuotaRetryAfter = time.Hour
+
+// respondOrgError 输出组织权限相关错误，返回是否已处理
+func respondOrgError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrNotOrgMember),
+		errors.Is(err, service.ErrOrgOwnerRequired),
+		errors.Is(err, service.ErrCannotRemoveOrgOwner),
+		errors.Is(err, service.ErrInviteEmailMismatch):
+		Forbidden(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrOrgNotFound):
+		NotFound(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrInviteInvalid):
+		BadRequest(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrOrgQuotaExceeded):
+		middleware.RespondThrottled(c, middleware.Throttle{
+			Status:     http.StatusTooManyRequests,
+			ErrorCode:  middleware.ErrorCodeQuotaExceeded,
+			Message:    GetErrorMessage(err),
+			RetryAfter: orgQuotaRetryAfter,
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+// Create godoc
+// @Summary 创建组织
+// @Description 创建组织，创建者成为组织所有者
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param request body CreateOrgRequest true "组织信息"
+// @Success 201 {object} domain.Organization
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Router /v1/orgs [post]
+func (h *OrgHandler) Create(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	var req CreateOrgRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	org, err := h.service.Create(c.Request.Context(), userID, req.Name)
+	if err != nil {
+		if errors.Is(err, service.ErrOrgNameRequired) {
+			BadRequest(c, GetErrorMessage(err))
+			return
+		}
+		InternalError(c, "创建组织失败")
+		return
+	}
+
+	Created(c, org)
+}
+
+// List godoc
+// @Summary 我的组织
+// @Description 列出当前用户所在的组织及角色
+// @Tags Organizations
+// @Produce json
+// @Success 200 {array} service.OrgWithRole
+// @Failure 401 {object} Response
+// @Router /v1/orgs [get]
+func (h *OrgHandler) List(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	orgs, err := h.service.ListForUser(c.Request.Context(), userID)
+	if err != nil {
+		InternalError(c, "获取组织列表失败")
+		return
+	}
+
+	Success(c, orgs)
+}
+
+// Invite godoc
+// @Summary 邀请成员
+// @Description 组织所有者邀请用户加入，返回一次性加入令牌（7 天有效，受邀者以成员角色加入）
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param id path string true "组织ID"
+// @Param request body InviteOrgMemberRequest true "受邀邮箱"
+// @Success 201 {object} domain.OrgInvite
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Router /v1/orgs/{id}/invites [post]
+func (h *OrgHandler) Invite(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	var req InviteOrgMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	invite, err := h.service.Invite(c.Request.Context(), userID, c.Param("id"), req.Email)
+	if err != nil {
+		if !respondOrgError(c, err) {
+			InternalError(c, "创建邀请失败")
+		}
+		return
+	}
+
+	Created(c, invite)
+}
+
+// Join godoc
+// @Summary 加入组织
+// @Description 凭邀请令牌加入组织，令牌只能使用一次且必须与当前账号邮箱一致
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param request body JoinOrgRequest true "加入令牌"
+// @Success 200 {object} domain.OrgMember
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Router /v1/orgs/join [post]
+func (h *OrgHandler) Join(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	var req JoinOrgRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	member, err := h.service.Accept(c.Request.Context(), userID, c.GetString("email"), req.Token)
+	if err != nil {
+		if !respondOrgError(c, err) {
+			InternalError(c, "加入组织失败")
+		}
+		return
+	}
+
+	Success(c, member)
+}
+
+// ListMembers godoc
+// @Summary 组织成员
+// @Description 列出组织成员（仅成员可见）
+// @Tags Organizations
+// @Produce json
+// @Param id path string true "组织ID"
+// @Success 200 {array} domain.OrgMember
+// @Failure 403 {object} Response
+// @Router /v1/orgs/{id}/members [get]
+func (h *OrgHandler) ListMembers(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	members, err := h.service.ListMembers(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		if !respondOrgError(c, err) {
+			InternalError(c, "获取成员列表失败")
+		}
+		return
+	}
+	if members == nil {
+		members = []*domain.OrgMember{}
+	}
+
+	Success(c, members)
+}
+
+// RemoveMember godoc
+// @Summary 移除成员
+// @Description 组织所有者移除成员，或成员退出组织；组织创建者不能被移除
+// @Tags Organizations
+// @Param id path string true "组织ID"
+// @Param userId path string true "成员用户ID"
+// @Success 204
+// @Failure 403 {object} Response
+// @Router /v1/orgs/{id}/members/{userId} [delete]
+func (h *OrgHandler) RemoveMember(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	if err := h.service.RemoveMember(c.Request.Context(), userID, c.Param("id"), c.Param("userId")); err != nil {
+		if !respondOrgError(c, err) {
+			InternalError(c, "移除成员失败")
+		}
+		return
+	}
+
+	NoContent(c)
+}
@@ -3,18 +3,21 @@ package httptransport
 import (
 	"github.com/gin-gonic/gin"
 
+	"tempmail/backend/internal/domain"
 	"tempmail/backend/internal/service"
 )
 
 // PublicHandler 公开API处理器（无需认证）
 type PublicHandler struct {
 	systemDomainService *service.SystemDomainService
+	configService       *service.ConfigService
 }
 
 // NewPublicHandler 创建公开API处理器
-func NewPublicHandler(systemDomainService *service.SystemDomainService) *PublicHandler {
+func NewPublicHandler(systemDomainService *service.SystemDomainService, configService *service.ConfigService) *PublicHandler {
 	return &PublicHandler{
 		systemDomainService: systemDomainService,
+		configService:       configService,
 	}
 }
 
@@ -27,7 +30,7 @@ func NewPublicHandler(systemDomainService *service.SystemDomainService) *PublicH
 // @Router /v1/public/domains [get]
 func (h *PublicHandler) GetAvailableDomains(c *gin.Context) {
 	// 获取已激活的系统域名
-	domains, err := h.systemDomainService.GetActiveDomains()
+	domains, err := h.systemDomainService.GetActiveDomains(c.Request.Context())
 	if err != nil {
 		InternalError(c, "获取可用域名失败")
 		return
@@ -55,11 +58,11 @@ func (h *PublicHandler) GetAvailableDomains(c *gin.Context) {
 // @Description 获取前端需要的公开系统配置（公开接口，无需认证）
 // @Tags Public
 // @Produce json
-// @Success 200 {object} Response{data=object{domains=[]string,defaultDomain=string,features=object}}
+// @Success 200 {object} Response{data=object{domains=[]string,defaultDomain=string,features=object,maintenance=object,messageRetention=object}}
 // @Router /v1/public/config [get]
 func (h *PublicHandler) GetSystemConfig(c *gin.Context) {
 	// 获取已激活的系统域名
-	domains, err := h.systemDomainService.GetActiveDomains()
+	domains, err := h.systemDomainService.GetActiveDomains(c.Request.Context())
 	if err != nil {
 		InternalError(c, "获取系统配置失败")
 		return
@@ -81,9 +84,33 @@ func (h *PublicHandler) GetSystemConfig(c *gin.Context) {
 		defaultDomain = domainList[0]
 	}
 
+	maintenance := gin.H{"readOnly": false}
+	if h.configService != nil {
+		state := h.configService.Maintenance()
+		maintenance = gin.H{
+			"readOnly": state.ReadOnly,
+			"message":  state.Message,
+		}
+	}
+
+	// 各等级的邮件保留时长（秒，0 表示不限制，邮件随邮箱过期）
+	var policy domain.RetentionPolicyConfig
+	if h.configService != nil {
+		policy = h.configService.MessageRetention()
+	}
+	tiers := gin.H{}
+	for _, tier := range domain.UserTiers {
+		tiers[string(tier)] = int64(policy.For(&tier).Seconds())
+	}
+
 	Success(c, gin.H{
 		"domains":       domainList,
 		"defaultDomain": defaultDomain,
+		"maintenance":   maintenance,
+		"messageRetention": gin.H{
+			"guest": int64(policy.For(nil).Seconds()),
+			"tiers": tiers,
+		},
 		"features": gin.H{
 			"websocket":   true,
 			"attachments": true,
@@ -0,0 +1,137 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+)
+
+// PublicInboxHandler 公开收件箱处理器（无需认证，只读）
+type PublicInboxHandler struct {
+	inboxes *service.PublicInboxService
+}
+
+// NewPublicInboxHandler 创建公开收件箱处理器
+func NewPublicInboxHandler(inboxes *service.PublicInboxService) *PublicInboxHandler {
+	return &PublicInboxHandler{inboxes: inboxes}
+}
+
+// ListInboxes godoc
+// @Summary 公开收件箱列表
+// @Description 列出所有公开收件箱及未读/总邮件数（公开接口，无需认证）
+// @Tags Public
+// @Produce json
+// @Success 200 {object} Response{data=object{items=[]service.PublicInbox,count=int}}
+// @Failure 429 {object} Response
+// @Router /v1/public/inboxes [get]
+func (h *PublicInboxHandler) ListInboxes(c *gin.Context) {
+	inboxes, err := h.inboxes.List(c.Request.Context())
+	if err != nil {
+		InternalError(c, MsgPublicInboxListFailed)
+		return
+	}
+
+	Success(c, gin.H{
+		"items": inboxes,
+		"count": len(inboxes),
+	})
+}
+
+// ListMessages godoc
+// @Summary 公开收件箱邮件列表
+// @Description 分页列出公开收件箱中的邮件预览（新邮件在前，邮件在 1 小时后自动删除）
+// @Tags Public
+// @Produce json
+// @Param address path string true "邮箱地址"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量（最大50）" default(20)
+// @Success 200 {object} Response{data=service.PublicMessagePage}
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
+// @Router /v1/public/inboxes/{address}/messages [get]
+func (h *PublicInboxHandler) ListMessages(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(service.DefaultPublicPageSize)))
+
+	result, err := h.inboxes.ListMessages(c.Request.Context(), c.Param("address"), page, pageSize)
+	if err != nil {
+		if !h.respondError(c, err) {
+			InternalError(c, MsgMessageListFailed)
+		}
+		return
+	}
+
+	Success(c, result)
+}
+
+// GetMessage godoc
+// @Summary 公开邮件详情
+// @Description 查看公开收件箱中的单封邮件（HTML 已清理，不标记已读）
+// @Tags Public
+// @Produce json
+// @Param address path string true "邮箱地址"
+// @Param messageId path string true "邮件ID"
+// @Success 200 {object} Response{data=service.PublicMessage}
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
+// @Router /v1/public/inboxes/{address}/messages/{messageId} [get]
+func (h *PublicInboxHandler) GetMessage(c *gin.Context) {
+	message, err := h.inboxes.GetMessage(c.Request.Context(), c.Param("address"), c.Param("messageId"))
+	if err != nil {
+		if !h.respondError(c, err) {
+			InternalError(c, MsgMessageGetFailed)
+		}
+		return
+	}
+
+	Success(c, message)
+}
+
+// DownloadAttachment godoc
+// @Summary 下载公开邮件附件
+// @Description 下载公开收件箱中邮件的附件（超过公开下载上限时返回 413）
+// @Tags Public
+// @Produce application/octet-stream
+// @Param address path string true "邮箱地址"
+// @Param messageId path string true "邮件ID"
+// @Param attachmentId path string true "附件ID"
+// @Success 200 {file} binary
+// @Failure 404 {object} Response
+// @Failure 413 {object} Response
+// @Failure 429 {object} Response
+// @Router /v1/public/inboxes/{address}/messages/{messageId}/attachments/{attachmentId} [get]
+func (h *PublicInboxHandler) DownloadAttachment(c *gin.Context) {
+	attachment, err := h.inboxes.GetAttachment(c.Request.Context(), c.Param("address"), c.Param("messageId"), c.Param("attachmentId"))
+	if err != nil {
+		if !h.respondError(c, err) {
+			NotFound(c, MsgAttachmentNotFound)
+		}
+		return
+	}
+
+	content, err := attachment.Open()
+	if err != nil {
+		InternalError(c, MsgAttachmentNotFound)
+		return
+	}
+	defer content.Close()
+
+	serveAttachment(c, attachment, content)
+}
+
+// respondError 响应公开收件箱的业务错误，不是业务错误时返回 false
+func (h *PublicInboxHandler) respondError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrPublicInboxNotFound), errors.Is(err, service.ErrPublicMessageNotFound):
+		NotFound(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrPublicAttachmentTooLarge):
+		Error(c, http.StatusRequestEntityTooLarge, GetErrorMessage(err))
+	default:
+		return false
+	}
+	return true
+}
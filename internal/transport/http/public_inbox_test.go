@@ -0,0 +1,87 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestPublicInbox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "lobby@temp.mail", LocalPart: "lobby", Domain: "temp.mail", Token: "secret-token",
+		IsPublic: true, CreatedAt: time.Now(),
+	}))
+	mailboxes := service.NewMailboxService(store, store, &config.Config{})
+	messages := service.NewMessageService(store)
+	msg, err := messages.Create(t.Context(), service.CreateMessageInput{
+		MailboxID: "mb-1", From: "a@example.com", Subject: "welcome", Text: "hello there",
+		HTML: `<p>hello</p><img src="x" onerror="steal()">`,
+	})
+	require.NoError(t, err)
+
+	h := NewPublicInboxHandler(service.NewPublicInboxService(store, messages))
+	mailboxAuth := middleware.NewMailboxAuth(mailboxes)
+	router := gin.New()
+	router.GET("/v1/public/inboxes", h.ListInboxes)
+	router.GET("/v1/public/inboxes/:address/messages", h.ListMessages)
+	router.GET("/v1/public/inboxes/:address/messages/:messageId", h.GetMessage)
+	router.POST("/v1/mailboxes/:id/messages/:messageId/read", mailboxAuth.RequireMailboxToken(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("无需凭据即可查看", func(t *testing.T) {
+		w := get("/v1/public/inboxes")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "lobby@temp.mail")
+
+		w = get("/v1/public/inboxes/lobby@temp.mail/messages")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data service.PublicMessagePage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Data.Items, 1)
+		assert.Equal(t, "hello there", resp.Data.Items[0].Preview)
+
+		w = get("/v1/public/inboxes/lobby@temp.mail/messages/" + msg.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "onerror")
+		assert.NotContains(t, w.Body.String(), "secret-token")
+	})
+
+	t.Run("写操作仍需令牌", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/mailboxes/mb-1/messages/"+msg.ID+"/read", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("取消公开后返回 404", func(t *testing.T) {
+		_, err := mailboxes.SetPublic(t.Context(), "mb-1", false)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusNotFound, get("/v1/public/inboxes/lobby@temp.mail/messages").Code)
+		assert.Equal(t, http.StatusNotFound, get("/v1/public/inboxes/lobby@temp.mail/messages/"+msg.ID).Code)
+		assert.NotContains(t, get("/v1/public/inboxes").Body.String(), "lobby@temp.mail")
+	})
+}
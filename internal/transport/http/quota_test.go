@@ -0,0 +1,145 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// assertQuotaExceeded 校验 403 响应的 X-Quota-* 头与 data 中的配额用量一致
+func assertQuotaExceeded(t *testing.T, w *httptest.ResponseRecorder, scope string, limit, used int) {
+	t.Helper()
+	require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Equal(t, scope, w.Header().Get("X-Quota-Scope"))
+	assert.Equal(t, strconv.Itoa(limit), w.Header().Get("X-Quota-Limit"))
+	assert.Equal(t, strconv.Itoa(used), w.Header().Get("X-Quota-Used"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	var resp struct {
+		Data struct {
+			ErrorCode string `json:"errorCode"`
+			Scope     string `json:"scope"`
+			Limit     int    `json:"limit"`
+			Used      int    `json:"used"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, middleware.ErrorCodeQuotaExceeded, resp.Data.ErrorCode)
+	assert.Equal(t, scope, resp.Data.Scope)
+	assert.Equal(t, limit, resp.Data.Limit)
+	assert.Equal(t, used, resp.Data.Used)
+}
+
+func TestUserQuotas(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(time.Hour)
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: "user-free", Email: "free@example.com", Username: "free", Tier: domain.TierFree, IsActive: true}))
+	require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: "user-pro", Email: "pro@example.com", Username: "pro", Tier: domain.TierPro, IsActive: true}))
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.mail"}, DefaultTTL: time.Hour}}
+	mailboxes := service.NewMailboxService(store, store, cfg)
+	h := &Handler{mailboxes: mailboxes, messages: service.NewMessageService(store)}
+
+	jwtManager := jwtpkg.NewManager("test-secret", "test", time.Hour, 24*time.Hour)
+	jwtAuth := middleware.NewJWTAuth(jwtManager)
+	jwtAuth.SetRequestQuota(middleware.NewRequestQuota(store, nil))
+	mailboxAuth := middleware.NewMailboxAuth(mailboxes)
+
+	router := gin.New()
+	router.GET("/v1/auth/me", jwtAuth.RequireAuth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/v1/mailboxes", jwtAuth.OptionalAuth(), h.createMailbox)
+	router.POST("/v1/mailboxes/batch", jwtAuth.RequireAuth(), h.batchCreateMailboxes)
+	router.POST("/v1/mailboxes/:id/messages", mailboxAuth.RequireMailboxToken(), h.createMessage)
+
+	token := func(userID string, tier domain.UserTier) string {
+		pair, err := jwtManager.GenerateTokenPair(t.Context(), userID, userID+"@example.com", string(tier))
+		require.NoError(t, err)
+		return pair.AccessToken
+	}
+	serve := func(method, path, bearer, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("每分钟请求数按用户等级限制", func(t *testing.T) {
+		free := token("user-rpm", domain.TierFree)
+		limit := domain.DefaultQuotas(domain.TierFree).MaxAPIRequestsPerMinute
+		for i := range limit {
+			w := serve(http.MethodGet, "/v1/auth/me", free, "")
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, strconv.Itoa(limit), w.Header().Get("X-RateLimit-Limit"))
+			assert.Equal(t, strconv.Itoa(limit-i-1), w.Header().Get("X-RateLimit-Remaining"))
+		}
+		w := serve(http.MethodGet, "/v1/auth/me", free, "")
+		assertThrottled(t, w, http.StatusTooManyRequests, middleware.ErrorCodeRateLimited)
+		reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+		require.NoError(t, err)
+		assert.Zero(t, reset%60, "按自然分钟分桶")
+
+		// 计数按用户隔离，企业版不限
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/auth/me", token("user-other", domain.TierFree), "").Code)
+		enterprise := token("user-enterprise", domain.TierEnterprise)
+		for range limit + 1 {
+			require.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/auth/me", enterprise, "").Code)
+		}
+	})
+
+	t.Run("游客请求不计入", func(t *testing.T) {
+		w := serve(http.MethodPost, "/v1/mailboxes", "", `{}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+	})
+
+	t.Run("个人邮箱数量达到等级上限", func(t *testing.T) {
+		free := token("user-free", domain.TierFree)
+		limit := domain.DefaultQuotas(domain.TierFree).MaxMailboxes
+		for range limit {
+			require.Equal(t, http.StatusCreated, serve(http.MethodPost, "/v1/mailboxes", free, `{}`).Code)
+		}
+		assertQuotaExceeded(t, serve(http.MethodPost, "/v1/mailboxes", free, `{}`), service.QuotaScopeUserMailboxes, limit, limit)
+
+		// 更高等级的上限更大，批量创建按整批检查
+		pro := token("user-pro", domain.TierPro)
+		proLimit := domain.DefaultQuotas(domain.TierPro).MaxMailboxes
+		w := serve(http.MethodPost, "/v1/mailboxes/batch", pro, `{"count":`+strconv.Itoa(proLimit+1)+`}`)
+		assertQuotaExceeded(t, w, service.QuotaScopeUserMailboxes, proLimit, 0)
+		assert.Empty(t, mailboxes.ListByUserID(t.Context(), "user-pro"), "超出配额时不创建任何邮箱")
+		require.Equal(t, http.StatusCreated, serve(http.MethodPost, "/v1/mailboxes/batch", pro, `{"count":`+strconv.Itoa(proLimit)+`}`).Code)
+	})
+
+	t.Run("邮箱邮件数量达到等级上限", func(t *testing.T) {
+		userID := "user-free"
+		mailbox, err := mailboxes.Create(t.Context(), service.CreateMailboxInput{})
+		require.NoError(t, err)
+		mailbox.UserID = &userID
+		require.NoError(t, store.SaveMailbox(t.Context(), mailbox))
+
+		limit := domain.DefaultQuotas(domain.TierFree).MaxMessagesPerMailbox
+		path := "/v1/mailboxes/" + mailbox.ID + "/messages"
+		for range limit {
+			require.Equal(t, http.StatusCreated, serve(http.MethodPost, path, mailbox.Token, `{"from":"a@example.com","subject":"hi"}`).Code)
+		}
+		assertQuotaExceeded(t, serve(http.MethodPost, path, mailbox.Token, `{"from":"a@example.com","subject":"hi"}`), service.QuotaScopeMailboxMessages, limit, limit)
+	})
+}
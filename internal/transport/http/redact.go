@@ -0,0 +1,93 @@
+package httptransport
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/redact"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage"
+	"tempmail/backend/internal/storage/memory"
+)
+
+type redactMessageRequest struct {
+	Rules    []string `json:"rules"`    // 内置规则：emails、phones、token_urls、codes
+	Patterns []string `json:"patterns"` // 自定义正则（RE2 语法，最多 10 条）
+}
+
+// createRedactedCopy godoc
+// @Summary 生成邮件脱敏副本
+// @Description 按内置规则和/或自定义正则遮盖主题、发件人和正文中的敏感内容（命中部分替换为 ██），HTML 正文清理后保留结构。每封邮件保留一份副本，重新生成时覆盖；原始邮件不受影响
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param messageId path string true "邮件ID"
+// @Param request body redactMessageRequest true "脱敏规则"
+// @Success 201 {object} Response{data=domain.MessageRedaction}
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/mailboxes/{id}/messages/{messageId}/redacted-copy [post]
+func (h *Handler) createRedactedCopy(c *gin.Context) {
+	var req redactMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	redaction, err := h.redactions.CreateCopy(c.Request.Context(), c.Param("id"), c.Param("messageId"), service.RedactInput{
+		Rules:    req.Rules,
+		Patterns: req.Patterns,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, redact.ErrUnknownRule), errors.Is(err, redact.ErrInvalidPattern):
+			BadRequest(c, redactErrorMessage(err)+": "+err.Error())
+		case errors.Is(err, redact.ErrNoRules), errors.Is(err, redact.ErrTooManyPatterns),
+			errors.Is(err, service.ErrNothingToRedact):
+			BadRequest(c, redactErrorMessage(err))
+		case errors.Is(err, memory.ErrMessageNotFound):
+			NotFound(c, MsgMessageNotFound)
+		default:
+			InternalError(c, MsgMessageRedactFailed)
+		}
+		return
+	}
+
+	Created(c, redaction)
+}
+
+// getRedactedCopy godoc
+// @Summary 获取邮件脱敏副本
+// @Description 返回最近一次生成的脱敏副本及生成时使用的规则和规则版本
+// @Tags Messages
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param messageId path string true "邮件ID"
+// @Success 200 {object} Response{data=domain.MessageRedaction}
+// @Failure 404 {object} Response
+// @Router /v1/mailboxes/{id}/messages/{messageId}/redacted [get]
+func (h *Handler) getRedactedCopy(c *gin.Context) {
+	redaction, err := h.redactions.Get(c.Request.Context(), c.Param("id"), c.Param("messageId"))
+	if err != nil {
+		if errors.Is(err, storage.ErrRedactionNotFound) {
+			NotFound(c, MsgRedactionNotFound)
+			return
+		}
+		InternalError(c, MsgInternalError)
+		return
+	}
+
+	Success(c, redaction)
+}
+
+// redactErrorMessage 脱敏错误的中文消息（redact 包的错误带有具体规则或正则）
+func redactErrorMessage(err error) string {
+	for _, target := range []error{redact.ErrNoRules, redact.ErrUnknownRule, redact.ErrInvalidPattern, redact.ErrTooManyPatterns} {
+		if errors.Is(err, target) {
+			return GetErrorMessage(target)
+		}
+	}
+	return GetErrorMessage(err)
+}
@@ -0,0 +1,107 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/redact"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestRedactedCopy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "qa@temp.mail", LocalPart: "qa", Domain: "temp.mail", CreatedAt: time.Now(),
+	}))
+	messages := service.NewMessageService(store)
+	msg, err := messages.Create(t.Context(), service.CreateMessageInput{
+		MailboxID: "mb-1",
+		From:      "noreply@bank.example",
+		Subject:   "Your code is 482913",
+		Text:      "Use 482913 or open https://bank.example/verify?token=abcdef to sign in.",
+		HTML:      `<p>Use <b>48</b>2913 or <a href="https://bank.example/verify?token=abcdef">verify</a></p><script>x()</script>`,
+	})
+	require.NoError(t, err)
+
+	h := &Handler{messages: messages, redactions: service.NewRedactionService(messages, store)}
+	router := gin.New()
+	router.POST("/v1/mailboxes/:id/messages/:messageId/redacted-copy", h.createRedactedCopy)
+	router.GET("/v1/mailboxes/:id/messages/:messageId/redacted", h.getRedactedCopy)
+
+	base := "/v1/mailboxes/mb-1/messages/" + msg.ID
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("生成前获取返回 404", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, base+"/redacted", "").Code)
+	})
+
+	t.Run("规则无效", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, base+"/redacted-copy", `{"rules":["ssn"]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, base+"/redacted-copy", `{"patterns":["(x"]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, base+"/redacted-copy", `{}`).Code)
+	})
+
+	t.Run("邮件不存在", func(t *testing.T) {
+		w := do(http.MethodPost, "/v1/mailboxes/mb-1/messages/missing/redacted-copy", `{"rules":["codes"]}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("生成并获取脱敏副本，不含原文", func(t *testing.T) {
+		w := do(http.MethodPost, base+"/redacted-copy", `{"rules":["codes","token_urls","emails"]}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		w = do(http.MethodGet, base+"/redacted", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		body := w.Body.String()
+		for _, secret := range []string{"482913", "abcdef", "noreply@bank.example", "<script"} {
+			assert.NotContains(t, body, secret)
+		}
+
+		var resp struct {
+			Data domain.MessageRedaction `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "Your code is ██", resp.Data.Subject)
+		assert.Equal(t, redact.Version, resp.Data.RulesVersion)
+		assert.Equal(t, []string{"codes", "emails", "token_urls"}, resp.Data.Rules)
+		assert.Contains(t, resp.Data.HTML, `<b>██</b>`)
+
+		original, err := messages.Get(t.Context(), "mb-1", msg.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Your code is 482913", original.Subject, "原始邮件不受影响")
+	})
+
+	t.Run("重新生成覆盖旧副本", func(t *testing.T) {
+		w := do(http.MethodPost, base+"/redacted-copy", `{"patterns":["bank\\.example"]}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		redaction, err := store.GetMessageRedaction(t.Context(), "mb-1", msg.ID)
+		require.NoError(t, err)
+		assert.Empty(t, redaction.Rules)
+		assert.Equal(t, []string{`bank\.example`}, redaction.Patterns)
+		assert.Equal(t, "Your code is 482913", redaction.Subject)
+	})
+
+	t.Run("删除邮件同时删除副本", func(t *testing.T) {
+		require.NoError(t, store.DeleteMessage(t.Context(), "mb-1", msg.ID))
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, base+"/redacted", "").Code)
+	})
+}
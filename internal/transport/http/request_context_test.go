@@ -0,0 +1,67 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// blockingListStore ListMessagesPage 阻塞到调用方上下文结束，用于模拟慢查询。
+type blockingListStore struct {
+	*memory.Store
+	started   chan struct{}
+	cancelled chan error
+}
+
+func (s *blockingListStore) ListMessagesPage(ctx context.Context, query domain.MessageListQuery) (*domain.MessagePage, error) {
+	close(s.started)
+	<-ctx.Done()
+	s.cancelled <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+func TestRequestContextCancelsStorage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &blockingListStore{
+		Store:     memory.NewStore(24 * time.Hour),
+		started:   make(chan struct{}),
+		cancelled: make(chan error, 1),
+	}
+	h := &Handler{messages: service.NewMessageService(store)}
+	router := gin.New()
+	router.GET("/v1/mailboxes/:id/messages", h.listMessages)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	req := httptest.NewRequest(http.MethodGet, "/v1/mailboxes/mb-1/messages", nil).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	<-store.started
+	// 客户端断开
+	cancel()
+
+	select {
+	case err := <-store.cancelled:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("存储层未观察到请求取消")
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("处理器未在请求取消后返回")
+	}
+}
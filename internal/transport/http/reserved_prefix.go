@@ -0,0 +1,221 @@
+package httptransport
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// ReservedPrefixHandler 保留前缀API处理器（管理员屏蔽前缀、用户预留靓号）
+type ReservedPrefixHandler struct {
+	reservedService *service.ReservedPrefixService
+}
+
+// NewReservedPrefixHandler 创建保留前缀处理器
+func NewReservedPrefixHandler(reservedService *service.ReservedPrefixService) *ReservedPrefixHandler {
+	return &ReservedPrefixHandler{reservedService: reservedService}
+}
+
+// blockPrefixRequest 管理员屏蔽前缀请求
+type blockPrefixRequest struct {
+	Domain    string     `json:"domain"` // 为空时对所有系统域名生效
+	LocalPart string     `json:"localPart" binding:"required"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expiresAt"` // 为空表示永久
+}
+
+// reservePrefixRequest 用户预留靓号前缀请求
+type reservePrefixRequest struct {
+	Domain    string `json:"domain"` // 为空时使用默认域名
+	LocalPart string `json:"localPart" binding:"required"`
+	Days      int    `json:"days"` // 预留天数，默认 30，最多 365
+}
+
+// reservedPrefixListResponse 管理员保留前缀列表
+type reservedPrefixListResponse struct {
+	Builtin []string                 `json:"builtin"` // 配置中禁止使用的前缀（mailbox.reserved_prefixes）
+	Items   []*domain.ReservedPrefix `json:"items"`
+}
+
+// List godoc
+// @Summary 获取保留前缀
+// @Description 列出配置中禁止使用的前缀、管理员屏蔽的前缀和用户预留的靓号前缀
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=reservedPrefixListResponse}
+// @Router /v1/admin/reserved-prefixes [get]
+func (h *ReservedPrefixHandler) List(c *gin.Context) {
+	prefixes, err := h.reservedService.List(c.Request.Context())
+	if err != nil {
+		InternalError(c, MsgReservedPrefixUpdateFailed)
+		return
+	}
+	if prefixes == nil {
+		prefixes = []*domain.ReservedPrefix{}
+	}
+	Success(c, reservedPrefixListResponse{
+		Builtin: h.reservedService.BuiltinPrefixes(),
+		Items:   prefixes,
+	})
+}
+
+// Block godoc
+// @Summary 屏蔽前缀
+// @Description 禁止在系统域名上创建该前缀的邮箱（已有邮箱不受影响），domain 为空时对所有系统域名生效
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body blockPrefixRequest true "屏蔽的前缀"
+// @Success 201 {object} Response{data=domain.ReservedPrefix}
+// @Failure 400 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/admin/reserved-prefixes [post]
+func (h *ReservedPrefixHandler) Block(c *gin.Context) {
+	var req blockPrefixRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	prefix, err := h.reservedService.Block(c.Request.Context(), service.BlockPrefixInput{
+		Domain:    req.Domain,
+		LocalPart: req.LocalPart,
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	Created(c, prefix)
+}
+
+// Remove godoc
+// @Summary 删除保留前缀
+// @Description 删除管理员屏蔽的前缀，或收回用户预留的靓号前缀
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path string true "保留前缀ID"
+// @Success 204
+// @Failure 404 {object} Response
+// @Router /v1/admin/reserved-prefixes/{id} [delete]
+func (h *ReservedPrefixHandler) Remove(c *gin.Context) {
+	if err := h.reservedService.Remove(c.Request.Context(), c.Param("id")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	NoContent(c)
+}
+
+// ListMine godoc
+// @Summary 获取我的靓号前缀
+// @Description 列出当前用户预留的靓号前缀（含已过期但未被他人预留的条目）
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=[]domain.ReservedPrefix}
+// @Router /v1/auth/me/reserved-prefixes [get]
+func (h *ReservedPrefixHandler) ListMine(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	prefixes, err := h.reservedService.ListForUser(c.Request.Context(), userID)
+	if err != nil {
+		InternalError(c, MsgReservedPrefixUpdateFailed)
+		return
+	}
+	if prefixes == nil {
+		prefixes = []*domain.ReservedPrefix{}
+	}
+	Success(c, prefixes)
+}
+
+// Reserve godoc
+// @Summary 预留靓号前缀
+// @Description 付费用户在共享域名上预留前缀，有效期内只有本人可以用该前缀创建邮箱；再次预留自己的前缀时按新的天数续期
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body reservePrefixRequest true "预留的前缀"
+// @Success 201 {object} Response{data=domain.ReservedPrefix}
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 409 {object} Response
+// @Router /v1/auth/me/reserved-prefixes [post]
+func (h *ReservedPrefixHandler) Reserve(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	var req reservePrefixRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Days < 0 {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	prefix, err := h.reservedService.Reserve(c.Request.Context(), userID, service.ReservePrefixInput{
+		Domain:    req.Domain,
+		LocalPart: req.LocalPart,
+		Duration:  time.Duration(req.Days) * 24 * time.Hour,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	Created(c, prefix)
+}
+
+// Release godoc
+// @Summary 释放靓号前缀
+// @Tags Auth
+// @Security BearerAuth
+// @Param id path string true "保留前缀ID"
+// @Success 204
+// @Failure 404 {object} Response
+// @Router /v1/auth/me/reserved-prefixes/{id} [delete]
+func (h *ReservedPrefixHandler) Release(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	if err := h.reservedService.Release(c.Request.Context(), userID, c.Param("id")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	NoContent(c)
+}
+
+// respondError 将保留前缀服务错误映射为响应
+func (h *ReservedPrefixHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrReservedPrefixNotFound), errors.Is(err, service.ErrUserNotFound):
+		NotFound(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrVanityRequiresPaidTier):
+		Forbidden(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrReservedPrefixInvalid), errors.Is(err, service.ErrReservedDomainInvalid),
+		errors.Is(err, service.ErrReservationPeriodInvalid), errors.Is(err, service.ErrVanityLimitReached):
+		BadRequest(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrReservedPrefixExists), errors.Is(err, service.ErrPrefixReserved):
+		Conflict(c, GetErrorMessage(err))
+	default:
+		InternalError(c, MsgReservedPrefixUpdateFailed)
+	}
+}
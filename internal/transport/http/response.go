@@ -29,7 +29,9 @@ const (
 	CodeUnprocessableEntity = 422 // 无法处理的实体
 
 	// 服务器错误 5xx
-	CodeInternalError = 500 // 服务器内部错误
+	CodeInternalError  = 500 // 服务器内部错误
+	CodeNotImplemented = 501 // 功能未启用
+	CodeBadGateway     = 502 // 上游服务错误
 )
 
 // Success 成功响应（200）
@@ -1,8 +1,11 @@
 package httptransport
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	gincors "github.com/gin-contrib/cors"
@@ -11,25 +14,39 @@ import (
 	swaggerFiles "github.com/swaggo/files"
 	"go.uber.org/zap"
 
+	"tempmail/backend/internal/analytics"
 	"tempmail/backend/internal/auth"
 	jwtpkg "tempmail/backend/internal/auth/jwt"
 	"tempmail/backend/internal/config"
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/jobs"
+	"tempmail/backend/internal/mailflow"
 	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/monitoring"
 	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/smtp"
 	"tempmail/backend/internal/storage"
+	"tempmail/backend/internal/storage/instrumented"
 	"tempmail/backend/internal/storage/memory"
 	"tempmail/backend/internal/websocket"
 )
 
 // Handler 聚合所有 HTTP 处理逻辑。
 type Handler struct {
-	mailboxes *service.MailboxService
-	messages  *service.MessageService
-	aliases   *service.AliasService
-	search    *service.SearchService
-	webhook   *service.WebhookService
-	tag       *service.TagService
+	mailboxes  *service.MailboxService
+	messages   *service.MessageService
+	aliases    *service.AliasService
+	search     *service.SearchService
+	webhook    *service.WebhookService
+	tag        *service.TagService
+	stats      *service.StatsService
+	redactions *service.RedactionService
+	idle       *service.MailboxIdleService // 记录邮箱访问时间（可选）
+	expiry     *service.MessageExpiryService
+	shares     *service.MessageShareService
+	export     *service.MailboxExportService
+	forwards   *service.ForwardingService
+	authz      *service.Authorizer
 }
 
 // RouterDependencies 路由器依赖项
@@ -42,11 +59,47 @@ type RouterDependencies struct {
 	WebhookService      *service.WebhookService      // 添加 Webhook 服务
 	TagService          *service.TagService          // 添加标签服务
 	AuthService         *auth.Service
+	OAuthService        *auth.OAuthService // 第三方登录（可选，配置了提供方时启用）
+	SessionService      *auth.SessionService // 登录会话：刷新令牌轮换、注销（可选）
+	AccountService      *service.AccountService // 用户自助账户管理（可选）
 	AdminService        *service.AdminService        // 添加管理服务
 	UserDomainService   *service.UserDomainService   // 添加用户域名服务
 	SystemDomainService *service.SystemDomainService // 添加系统域名服务
 	APIKeyService       *service.APIKeyService       // 添加API Key服务
 	ConfigService       *service.ConfigService       // 添加系统配置服务
+	BackupService       *service.SettingsBackupService // 配置导出/恢复服务
+	UserDataService     *service.UserDataService       // 个人数据导出（可选）
+	UserSpamService     *service.UserSpamService       // 用户级垃圾邮件阈值（可选）
+	RetentionService    *service.RetentionService      // 数据保留报告（可选）
+	StatsService        *service.StatsService          // 收件统计服务
+	OrgService          *service.OrgService            // 组织/团队服务
+	DistributionLists   *service.DistributionListService // 分发列表服务
+	RedactionService    *service.RedactionService        // 邮件脱敏副本服务
+	JWTKeyService       *service.JWTKeyService           // JWT 签名密钥轮换
+	SinkService         *service.SinkService             // 域名黑洞模式
+	CatchAllService     *service.CatchAllService         // 用户域名通配收件（可选）
+	DomainWhitelist     *service.DomainWhitelistService  // 用户域名白名单（可选）
+	MailboxIdleService  *service.MailboxIdleService      // 闲置邮箱检测（可选）
+	PublicInboxService  *service.PublicInboxService      // 公开收件箱（可选）
+	MessageExpiryService *service.MessageExpiryService   // 邮件到期规则（可选）
+	AbuseReportService  *service.AbuseReportService     // 滥用举报（可选）
+	MessageShareService *service.MessageShareService    // 邮件分享链接（可选）
+	MailboxExport       *service.MailboxExportService   // 邮箱导出（可选）
+	ForwardingService   *service.ForwardingService      // 邮箱转发（可选，需要发信中继）
+	MaintenanceJobs     *jobs.Runner                     // 维护任务（可选）
+	Analytics           *analytics.Collector             // 使用情况统计（可选）
+	MailFlow            *mailflow.Recorder               // 收信流量看板（可选）
+	ReservedPrefixes    *service.ReservedPrefixService   // 系统域名保留前缀（可选）
+	ImageProxy          *service.ImageProxy              // 邮件远程图片代理（可选，remote_images=proxy 时启用）
+	AuditService        *service.AuditService            // 管理操作审计记录（可选）
+	Impersonation       *service.ImpersonationService    // 客服代登录（可选）
+	StatusMonitor       *monitoring.StatusMonitor    // 公开状态监控（可选）
+	StoreRecorder       *instrumented.Recorder       // 存储调用计时与慢调用（可选）
+	SMTPSessions        *smtp.SessionRegistry        // 活跃 SMTP 会话（可选）
+	Snapshotter         StoreSnapshotter             // 内存存储快照导出（可选）
+	RequestQuota        *middleware.RequestQuota     // 按用户等级的每分钟请求配额（可选）
+	RateLimits          *middleware.RuntimeRateLimits // 系统配置控制的全局 IP 限流（可选）
+	DevMail             MailInjector                 // 开发模式发信（可选，仅 log.development 开启时注册）
 	JWTManager          *jwtpkg.Manager
 	WebSocketHub        *websocket.Hub // WebSocket Hub
 	Store               storage.Store  // 添加存储接口
@@ -57,7 +110,8 @@ type RouterDependencies struct {
 func NewRouter(deps RouterDependencies) *gin.Engine {
 	router := gin.New()
 
-	// 使用自定义中间件替代默认中间件
+	// 使用自定义中间件替代默认中间件（链路追踪在最外层，panic 恢复后的状态码也记录在 span 上）
+	router.Use(middleware.Tracing())
 	router.Use(middleware.RecoveryHandler())
 	router.Use(middleware.RequestLogger())
 	router.Use(middleware.SecurityHeaders())
@@ -76,6 +130,9 @@ func NewRouter(deps RouterDependencies) *gin.Engine {
 			"X-RateLimit-Limit",
 			"X-RateLimit-Remaining",
 			"X-RateLimit-Reset",
+			"X-Quota-Scope",
+			"X-Quota-Limit",
+			"X-Quota-Used",
 		},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -90,29 +147,64 @@ func NewRouter(deps RouterDependencies) *gin.Engine {
 	}
 	router.Use(gincors.New(corsConfig))
 
+	// 只读维护模式（需位于 CORS 之后，保证预检请求正常返回）
+	if deps.ConfigService != nil {
+		router.Use(middleware.ReadOnlyMode(deps.ConfigService))
+	}
+
 	// 创建处理器
 	handler := &Handler{
-		mailboxes: deps.MailboxService,
-		messages:  deps.MessageService,
-		aliases:   deps.AliasService,
-		search:    deps.SearchService,
-		webhook:   deps.WebhookService,
-		tag:       deps.TagService,
+		mailboxes:  deps.MailboxService,
+		messages:   deps.MessageService,
+		aliases:    deps.AliasService,
+		search:     deps.SearchService,
+		webhook:    deps.WebhookService,
+		tag:        deps.TagService,
+		stats:      deps.StatsService,
+		redactions: deps.RedactionService,
+		idle:       deps.MailboxIdleService,
+		expiry:     deps.MessageExpiryService,
+		shares:     deps.MessageShareService,
+		export:     deps.MailboxExport,
+		forwards:   deps.ForwardingService,
+		authz:      service.NewAuthorizer(deps.Store),
 	}
 
 	authHandler := NewAuthHandler(deps.AuthService, deps.JWTManager)
+	if deps.SessionService != nil {
+		authHandler.SetSessions(deps.SessionService)
+	}
 	adminHandler := NewAdminHandler(deps.AdminService, deps.SystemDomainService)                                                       // 创建管理处理器
 	userDomainHandler := NewUserDomainHandler(deps.UserDomainService)                                                                  // 创建用户域名处理器
 	apiKeyHandler := NewAPIKeyHandler(deps.APIKeyService)                                                                              // 创建API Key处理器
 	configHandler := NewConfigHandler(deps.ConfigService)                                                                              // 创建系统配置处理器
-	compatHandler := NewCompatHandler(deps.MailboxService, deps.MessageService, deps.AliasService, deps.Config.Mailbox.AllowedDomains) // 创建兼容API处理器
-	publicHandler := NewPublicHandler(deps.SystemDomainService)                                                                        // 创建公开API处理器
+	compatHandler := NewCompatHandler(deps.MailboxService, deps.MessageService, deps.AliasService) // 创建兼容API处理器
+	publicHandler := NewPublicHandler(deps.SystemDomainService, deps.ConfigService)                                                    // 创建公开API处理器
 
 	// 创建中间件
 	mailboxAuth := middleware.NewMailboxAuth(deps.MailboxService)
+	mailboxAuth.SetUserAccess(deps.JWTManager, handler.authz) // 邮箱所有者/组织成员可用 JWT 访问
 	jwtAuth := middleware.NewJWTAuth(deps.JWTManager)
+	if deps.APIKeyService != nil { // CLI 和脚本以 API Key 创建、访问自己的邮箱
+		jwtAuth.SetAPIKeys(deps.APIKeyService)
+		mailboxAuth.SetAPIKeys(deps.APIKeyService)
+	}
 	adminAuth := middleware.NewAdminAuth(deps.AuthService)     // 创建管理员中间件
+	if deps.ConfigService != nil { // 系统配置可要求管理员启用两步验证
+		adminAuth.SetTwoFactorPolicy(deps.ConfigService)
+	}
 	apiKeyAuth := middleware.NewAPIKeyAuth(deps.APIKeyService) // 创建API Key中间件
+	if deps.RequestQuota != nil { // 识别出用户后按等级限制每分钟请求数
+		jwtAuth.SetRequestQuota(deps.RequestQuota)
+		mailboxAuth.SetRequestQuota(deps.RequestQuota)
+		apiKeyAuth.SetRequestQuota(deps.RequestQuota)
+	}
+
+	// 功能调用统计（未启用统计时不记录）
+	var featureRecorder middleware.FeatureRecorder
+	if deps.Analytics != nil && deps.Analytics.Enabled() {
+		featureRecorder = deps.Analytics
+	}
 
 	// 限流中间件（临时禁用 - 开发环境）
 	// rateLimitStore := deps.Store.(storage.RateLimitRepository)
@@ -142,17 +234,80 @@ func NewRouter(deps RouterDependencies) *gin.Engine {
 
 	// 健康检查
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		resp := gin.H{"status": "ok"}
+		if deps.ConfigService != nil {
+			maintenance := deps.ConfigService.Maintenance()
+			resp["maintenance"] = gin.H{
+				"readOnly": maintenance.ReadOnly,
+				"message":  maintenance.Message,
+			}
+		}
+		c.JSON(http.StatusOK, resp)
 	})
 
 	// V1 API
 	v1 := router.Group("/v1")
+	if deps.RateLimits != nil { // 系统配置 rateLimit 节，管理员修改后即时生效
+		v1.Use(deps.RateLimits.API.Middleware())
+	}
 	{
 		// ========== Public Routes（无需认证的公开API） ==========
 		publicRoutes := v1.Group("/public")
 		{
 			publicRoutes.GET("/domains", publicHandler.GetAvailableDomains) // 获取可用域名列表
 			publicRoutes.GET("/config", publicHandler.GetSystemConfig)      // 获取系统配置
+
+			// 公开状态页（适度限流）
+			if deps.StatusMonitor != nil {
+				statusHandler := NewStatusHandler(deps.StatusMonitor)
+				statusLimit := middleware.IPRateLimit(30, time.Minute)
+				publicRoutes.GET("/status", statusLimit, statusHandler.GetStatus)
+				publicRoutes.GET("/status/history", statusLimit, statusHandler.GetStatusHistory)
+			}
+
+			// 公开收件箱（只读，必须限流）
+			if deps.PublicInboxService != nil {
+				inboxHandler := NewPublicInboxHandler(deps.PublicInboxService)
+				inboxLimit := middleware.IPRateLimit(60, time.Minute)
+				publicRoutes.GET("/inboxes", inboxLimit, inboxHandler.ListInboxes)
+				publicRoutes.GET("/inboxes/:address/messages", inboxLimit, inboxHandler.ListMessages)
+				publicRoutes.GET("/inboxes/:address/messages/:messageId", inboxLimit, inboxHandler.GetMessage)
+				publicRoutes.GET("/inboxes/:address/messages/:messageId/attachments/:attachmentId", inboxLimit, inboxHandler.DownloadAttachment)
+			}
+
+			// 滥用举报（严格限流，只接受小体积 JSON）
+			if deps.AbuseReportService != nil {
+				abuseHandler := NewAbuseReportHandler(deps.AbuseReportService)
+				publicRoutes.POST("/abuse-reports", middleware.IPRateLimit(5, time.Hour), middleware.BodySizeLimit(16*1024), abuseHandler.Submit)
+			}
+		}
+
+		// ========== Shared Routes（凭分享链接的签名令牌只读访问单封邮件或整个邮箱，适度限流） ==========
+		if deps.MessageShareService != nil {
+			sharedHandler := NewSharedMessageHandler(deps.MessageShareService)
+			sharedRoutes := v1.Group("/shared", middleware.IPRateLimit(60, time.Minute))
+			sharedRoutes.GET("/messages/:token", sharedHandler.GetMessage)
+			sharedRoutes.GET("/messages/:token/attachments/:attachmentId", sharedHandler.DownloadAttachment)
+			sharedRoutes.GET("/mailboxes/:token", sharedHandler.GetMailbox)
+			sharedRoutes.GET("/mailboxes/:token/messages/:messageId", sharedHandler.GetMailboxMessage)
+			sharedRoutes.GET("/mailboxes/:token/messages/:messageId/attachments/:attachmentId", sharedHandler.DownloadMailboxAttachment)
+		}
+
+		// ========== Image Proxy（安全渲染的邮件正文中的远程图片，地址带签名，适度限流） ==========
+		if deps.ImageProxy != nil {
+			imageProxyHandler := NewImageProxyHandler(deps.ImageProxy)
+			v1.GET("/image-proxy", middleware.IPRateLimit(300, time.Minute), imageProxyHandler.Fetch)
+		}
+
+		// ========== Dev Routes（仅开发模式，无需认证，只能投递到本实例的邮箱） ==========
+		if deps.DevMail != nil && deps.Config.Log.Development {
+			devHandler := NewDevHandler(deps.DevMail)
+			devRoutes := v1.Group("/dev", middleware.IPRateLimit(120, time.Minute)) // 宽松限流，防止脚本失控
+			{
+				devRoutes.GET("/templates", devHandler.ListTemplates)
+				devRoutes.POST("/send", devHandler.SendMail)
+				devRoutes.POST("/send/batch", devHandler.SendBatch)
+			}
 		}
 
 		// 应用全局限流和防滥用中间件（临时禁用 - 开发环境）
@@ -168,52 +323,173 @@ func NewRouter(deps RouterDependencies) *gin.Engine {
 			authRoutes.POST("/login", authHandler.Login)
 			authRoutes.POST("/refresh", authHandler.Refresh)
 			authRoutes.GET("/me", jwtAuth.RequireAuth(), authHandler.Me)
+			authRoutes.POST("/2fa/login", authHandler.LoginTwoFactor) // 登录第二步（质询令牌 + 验证码）
+			if deps.SessionService != nil {
+				authRoutes.POST("/logout", jwtAuth.RequireAuth(), authHandler.Logout)
+			}
+
+			// 凭据类接口：代登录令牌一律拒绝（即使是 GET）
+			credentialRoutes := authRoutes.Group("", jwtAuth.RequireAuth(), middleware.DenyImpersonation())
+			credentialRoutes.GET("/me/app-password", authHandler.AppPassword) // IMAP 客户端登录用
+			credentialRoutes.POST("/me/app-password/reset", authHandler.ResetAppPassword)
+			credentialRoutes.POST("/2fa/setup", authHandler.SetupTwoFactor)
+			credentialRoutes.POST("/2fa/verify", authHandler.VerifyTwoFactor)
+			credentialRoutes.POST("/2fa/disable", authHandler.DisableTwoFactor)
+			if deps.SessionService != nil {
+				credentialRoutes.GET("/sessions", authHandler.ListSessions)
+				credentialRoutes.DELETE("/sessions/:id", authHandler.RevokeSession) // 在其他设备上登出
+			}
+			if deps.OAuthService != nil {
+				oauthHandler := NewOAuthHandler(deps.OAuthService, deps.JWTManager, deps.Config.OAuth, deps.Logger)
+				oauthHandler.SetSessions(deps.SessionService)
+				authRoutes.GET("/oauth/providers", oauthHandler.Providers)
+				authRoutes.GET("/oauth/:provider/start", oauthHandler.Start)
+				authRoutes.GET("/oauth/:provider/callback", oauthHandler.Callback)
+			}
+			if deps.UserDataService != nil {
+				userDataHandler := NewUserDataHandler(deps.UserDataService, deps.RetentionService)
+				authRoutes.GET("/me/data-export", jwtAuth.RequireAuth(), middleware.FeatureUsage(featureRecorder, analytics.FeatureExport), userDataHandler.ExportMyData) // 导出个人数据（每小时一次）
+			}
+			if deps.UserSpamService != nil {
+				userSpamHandler := NewUserSpamHandler(deps.UserSpamService)
+				authRoutes.PUT("/me/spam-thresholds", jwtAuth.RequireAuth(), userSpamHandler.UpdateThresholds) // 名下邮箱的默认垃圾邮件阈值
+			}
+			if deps.ReservedPrefixes != nil {
+				reservedHandler := NewReservedPrefixHandler(deps.ReservedPrefixes)
+				authRoutes.GET("/me/reserved-prefixes", jwtAuth.RequireAuth(), reservedHandler.ListMine)
+				authRoutes.POST("/me/reserved-prefixes", jwtAuth.RequireAuth(), reservedHandler.Reserve) // 付费用户预留靓号前缀
+				authRoutes.DELETE("/me/reserved-prefixes/:id", jwtAuth.RequireAuth(), reservedHandler.Release)
+			}
+		}
+
+		// ========== User Profile Routes ==========
+		if deps.AccountService != nil {
+			accountHandler := NewAccountHandler(deps.AccountService, deps.Logger)
+			profileRoutes := v1.Group("/user/profile")
+			profileRoutes.Use(jwtAuth.RequireAuth())
+			{
+				profileRoutes.GET("", accountHandler.GetProfile)
+				profileRoutes.PATCH("", accountHandler.UpdateProfile)                           // 修改用户名
+				profileRoutes.PUT("/password", accountHandler.ChangePassword)                   // 修改密码
+				profileRoutes.POST("/email/verification", accountHandler.SendEmailVerification) // 发送邮箱验证码
+				profileRoutes.POST("/email/verify", accountHandler.VerifyEmail)                 // 提交验证码
+				profileRoutes.DELETE("", accountHandler.DeleteAccount)                          // 注销账户
+			}
 		}
 
 		// ========== Mailbox Routes ==========
 		mailboxRoutes := v1.Group("/mailboxes")
 		{
 			// 邮箱创建限流
-			mailboxRoutes.POST("", jwtAuth.OptionalAuth(), handler.createMailbox)
+			createLimit := func(c *gin.Context) { c.Next() }
+			if deps.RateLimits != nil {
+				createLimit = deps.RateLimits.CreateMailbox.Middleware()
+			}
+			mailboxRoutes.POST("", createLimit, jwtAuth.OptionalAuth(), handler.createMailbox)
+			mailboxRoutes.POST("/batch", jwtAuth.RequireAuth(), handler.batchCreateMailboxes) // 批量创建（测试自动化）
 			mailboxRoutes.GET("", jwtAuth.OptionalAuth(), handler.listMailboxes)
 
 			// 需要邮箱Token的端点
 			mailboxRoutes.GET("/:id", mailboxAuth.RequireMailboxToken(), handler.getMailbox)
+			mailboxRoutes.PATCH("/:id", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.updateMailbox)
+			mailboxRoutes.POST("/:id/extend", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.extendMailbox)
 			mailboxRoutes.DELETE("/:id", mailboxAuth.RequireMailboxToken(), handler.deleteMailbox)
+			mailboxRoutes.POST("/:id/claim", jwtAuth.RequireAuth(), handler.claimMailbox) // 登录后认领游客邮箱（凭邮箱令牌）
 
 			// 邮件相关端点（需要邮箱Token）
-			mailboxRoutes.POST("/:id/messages", mailboxAuth.RequireMailboxToken(), handler.createMessage)
+			mailboxRoutes.POST("/:id/messages", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.createMessage)
 			mailboxRoutes.GET("/:id/messages", mailboxAuth.RequireMailboxToken(), handler.listMessages)
 			mailboxRoutes.GET("/:id/messages/:messageId", mailboxAuth.RequireMailboxToken(), handler.getMessage)
-			mailboxRoutes.POST("/:id/messages/:messageId/read", mailboxAuth.RequireMailboxToken(), handler.markMessageRead)
+			mailboxRoutes.DELETE("/:id/messages", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.clearMessages)
+			mailboxRoutes.DELETE("/:id/messages/:messageId", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.deleteMessage)
+			mailboxRoutes.POST("/:id/messages/:messageId/read", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.markMessageRead)
+			mailboxRoutes.POST("/:id/messages/:messageId/translate", mailboxAuth.RequireMailboxToken(), handler.translateMessage)
 
 			// 附件下载端点
 			mailboxRoutes.GET("/:id/messages/:messageId/attachments/:attachmentId", mailboxAuth.RequireMailboxToken(), handler.downloadAttachment)
+			mailboxRoutes.GET("/:id/messages/:messageId/raw", mailboxAuth.RequireMailboxToken(), handler.downloadMessageRaw)
+			mailboxRoutes.GET("/:id/messages/:messageId/html", mailboxAuth.RequireMailboxToken(), handler.getMessageHTML) // 安全渲染的 HTML 正文
+			mailboxRoutes.GET("/:id/messages/:messageId/otp", mailboxAuth.RequireMailboxToken(), handler.getMessageOTP) // 提取的验证码和验证链接
+			mailboxRoutes.GET("/:id/otp", mailboxAuth.RequireMailboxToken(), handler.getLatestOTP) // 最新一封邮件的验证码
 
 			// 邮件搜索端点
-			mailboxRoutes.GET("/:id/messages/search", mailboxAuth.RequireMailboxToken(), handler.searchMessages)
+			mailboxRoutes.GET("/:id/messages/search", mailboxAuth.RequireMailboxToken(), middleware.FeatureUsage(featureRecorder, analytics.FeatureSearch), handler.searchMessages)
 
 			// 别名管理端点
-			mailboxRoutes.POST("/:id/aliases", mailboxAuth.RequireMailboxToken(), handler.createAlias)
+			mailboxRoutes.POST("/:id/aliases", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.createAlias)
 			mailboxRoutes.GET("/:id/aliases", mailboxAuth.RequireMailboxToken(), handler.listAliases)
 			mailboxRoutes.GET("/:id/aliases/:aliasId", mailboxAuth.RequireMailboxToken(), handler.getAlias)
-			mailboxRoutes.DELETE("/:id/aliases/:aliasId", mailboxAuth.RequireMailboxToken(), handler.deleteAlias)
-			mailboxRoutes.PATCH("/:id/aliases/:aliasId", mailboxAuth.RequireMailboxToken(), handler.toggleAlias)
+			mailboxRoutes.DELETE("/:id/aliases/:aliasId", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.deleteAlias)
+			mailboxRoutes.PATCH("/:id/aliases/:aliasId", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.toggleAlias)
 
 			// 邮件标签端点（需要邮箱Token）
-			mailboxRoutes.POST("/:id/messages/:messageId/tags", mailboxAuth.RequireMailboxToken(), handler.addMessageTag)
+			mailboxRoutes.POST("/:id/messages/:messageId/tags", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.addMessageTag)
 			mailboxRoutes.GET("/:id/messages/:messageId/tags", mailboxAuth.RequireMailboxToken(), handler.getMessageTags)
-			mailboxRoutes.DELETE("/:id/messages/:messageId/tags/:tagId", mailboxAuth.RequireMailboxToken(), handler.removeMessageTag)
+			mailboxRoutes.DELETE("/:id/messages/:messageId/tags/:tagId", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.removeMessageTag)
+
+			// 邮件脱敏副本端点（需要邮箱Token）
+			if deps.RedactionService != nil {
+				mailboxRoutes.POST("/:id/messages/:messageId/redacted-copy", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.createRedactedCopy)
+				mailboxRoutes.GET("/:id/messages/:messageId/redacted", mailboxAuth.RequireMailboxToken(), handler.getRedactedCopy)
+			}
+
+			// 邮件和邮箱分享链接端点（需要邮箱Token）
+			if deps.MessageShareService != nil {
+				mailboxRoutes.POST("/:id/messages/:messageId/share", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.createMessageShare)
+				mailboxRoutes.POST("/:id/shares", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.createMailboxShare)
+				mailboxRoutes.GET("/:id/shares", mailboxAuth.RequireMailboxToken(), handler.listMessageShares)
+				mailboxRoutes.DELETE("/:id/shares/:shareId", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.revokeMessageShare)
+			}
+
+			// 发信（回复）端点（需要邮箱Token，未配置中继时返回 503）
+			mailboxRoutes.POST("/:id/messages/send", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.sendMessage)
+			mailboxRoutes.GET("/:id/sent", mailboxAuth.RequireMailboxToken(), handler.listSentMessages)
+
+			// 转发到外部地址（需要发信中继）
+			if deps.ForwardingService != nil {
+				mailboxRoutes.GET("/:id/forwards", mailboxAuth.RequireMailboxToken(), handler.listMailboxForwards)
+				mailboxRoutes.POST("/:id/forwards", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.createMailboxForward)
+				mailboxRoutes.POST("/:id/forwards/:forwardId/verify", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.verifyMailboxForward)
+				mailboxRoutes.DELETE("/:id/forwards/:forwardId", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.deleteMailboxForward)
+			}
+
+			// 邮箱导出（mbox / eml zip）
+			if deps.MailboxExport != nil {
+				mailboxRoutes.GET("/:id/export", mailboxAuth.RequireMailboxToken(), handler.exportMailbox)
+			}
+
+			// 收件统计端点（需要邮箱Token）
+			if deps.StatsService != nil {
+				mailboxRoutes.GET("/:id/stats", mailboxAuth.RequireMailboxToken(), handler.mailboxStats)
+			}
+
+			// 邮件到期规则端点（需要邮箱Token）
+			if deps.MessageExpiryService != nil {
+				mailboxRoutes.GET("/:id/expiry-rules", mailboxAuth.RequireMailboxToken(), handler.getExpiryRules)
+				mailboxRoutes.PUT("/:id/expiry-rules", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.updateExpiryRules)
+			}
+
+			// 邮箱 Webhook 端点（需要邮箱Token，无需登录）
+			if deps.WebhookService != nil {
+				mailboxRoutes.POST("/:id/webhooks", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), middleware.FeatureUsage(featureRecorder, analytics.FeatureWebhookCreated), handler.createMailboxWebhook)
+				mailboxRoutes.GET("/:id/webhooks", mailboxAuth.RequireMailboxToken(), handler.listMailboxWebhooks)
+				mailboxRoutes.DELETE("/:id/webhooks/:webhookId", mailboxAuth.RequireMailboxToken(), mailboxAuth.RequireWritable(), handler.deleteMailboxWebhook)
+			}
 		}
 
 		// ========== WebSocket Routes ==========
 		if deps.WebSocketHub != nil {
 			v1.GET("/ws", websocket.HandleWebSocket(deps.WebSocketHub))
+			// 不便使用 WebSocket 的客户端（代理、无服务器函数）可用 SSE 订阅单个邮箱
+			v1.GET("/mailboxes/:id/events", mailboxAuth.RequireMailboxToken(), websocket.HandleSSE(deps.WebSocketHub))
 		}
 
 		// ========== Admin Routes ==========
 		adminRoutes := v1.Group("/admin")
 		adminRoutes.Use(jwtAuth.RequireAuth()) // 所有管理路由都需要认证
+		if deps.AuditService != nil {
+			adminRoutes.Use(middleware.AdminAudit(deps.AuditService, deps.Logger)) // 记录成功的管理写操作
+		}
 		{
 			// 用户管理（需要管理员权限）
 			adminRoutes.GET("/users", adminAuth.RequireAdmin(), adminHandler.ListUsers)
@@ -221,20 +497,88 @@ func NewRouter(deps RouterDependencies) *gin.Engine {
 			adminRoutes.PATCH("/users/:id", adminAuth.RequireAdmin(), adminHandler.UpdateUser)
 			adminRoutes.DELETE("/users/:id", adminAuth.RequireSuper(), adminHandler.DeleteUser) // 超级管理员才能删除用户
 
+			// 邮箱管理
+			adminRoutes.GET("/mailboxes", adminAuth.RequireAdmin(), adminHandler.ListMailboxes)              // 邮箱列表（含闲置状态）
+			adminRoutes.DELETE("/mailboxes/:id", adminAuth.RequireAdmin(), adminHandler.ForceDeleteMailbox) // 强制删除邮箱
+			adminRoutes.PATCH("/mailboxes/:id/public", adminAuth.RequireAdmin(), adminHandler.SetMailboxPublic) // 设置公开收件箱
+
+			// 滥用举报分拣与处理
+			if deps.AbuseReportService != nil {
+				abuseHandler := NewAbuseReportHandler(deps.AbuseReportService)
+				adminRoutes.GET("/abuse-reports", adminAuth.RequireAdmin(), abuseHandler.List)
+				adminRoutes.GET("/abuse-reports/:id", adminAuth.RequireAdmin(), abuseHandler.Get)
+				adminRoutes.POST("/abuse-reports/:id/triage", adminAuth.RequireAdmin(), abuseHandler.Triage)
+				adminRoutes.POST("/abuse-reports/:id/resolve", adminAuth.RequireAdmin(), abuseHandler.Resolve)
+				adminRoutes.PATCH("/mailboxes/:id/suspended", adminAuth.RequireAdmin(), abuseHandler.SetMailboxSuspended) // 停用/恢复邮箱
+			}
+
+			// 维护任务（回填、重新统计）
+			if deps.MaintenanceJobs != nil {
+				jobHandler := NewMaintenanceJobHandler(deps.MaintenanceJobs)
+				adminRoutes.POST("/jobs", adminAuth.RequireAdmin(), jobHandler.Create)
+				adminRoutes.GET("/jobs", adminAuth.RequireAdmin(), jobHandler.List)
+				adminRoutes.GET("/jobs/:id", adminAuth.RequireAdmin(), jobHandler.Get)
+				adminRoutes.POST("/jobs/:id/cancel", adminAuth.RequireAdmin(), jobHandler.Cancel)
+			}
+
+			// 使用情况统计（只含汇总计数）
+			if deps.Analytics != nil {
+				analyticsHandler := NewAnalyticsHandler(deps.Analytics)
+				adminRoutes.GET("/analytics", adminAuth.RequireAdmin(), analyticsHandler.Query)
+			}
+
+			// 收信流量看板
+			if deps.MailFlow != nil {
+				mailFlowHandler := NewMailFlowHandler(deps.MailFlow)
+				adminRoutes.GET("/mailflow", adminAuth.RequireAdmin(), mailFlowHandler.Report)
+			}
+
+			// 保留前缀（系统域名上禁止使用的前缀和用户靓号）
+			if deps.ReservedPrefixes != nil {
+				reservedHandler := NewReservedPrefixHandler(deps.ReservedPrefixes)
+				adminRoutes.GET("/reserved-prefixes", adminAuth.RequireAdmin(), reservedHandler.List)
+				adminRoutes.POST("/reserved-prefixes", adminAuth.RequireAdmin(), reservedHandler.Block)
+				adminRoutes.DELETE("/reserved-prefixes/:id", adminAuth.RequireAdmin(), reservedHandler.Remove)
+			}
+
 			// 用户配额管理
 			adminRoutes.GET("/users/:id/quota", adminAuth.RequireAdmin(), adminHandler.GetUserQuota)
 			adminRoutes.PUT("/users/:id/quota", adminAuth.RequireAdmin(), adminHandler.UpdateUserQuota)
 
+			// 代登录（只读短期令牌）
+			if deps.Impersonation != nil {
+				impersonationHandler := NewImpersonationHandler(deps.Impersonation)
+				adminRoutes.POST("/users/:id/impersonate", adminAuth.RequireAdmin(), impersonationHandler.Impersonate)
+			}
+
+			// 审计记录
+			if deps.AuditService != nil {
+				auditHandler := NewAuditLogHandler(deps.AuditService)
+				adminRoutes.GET("/audit-logs", adminAuth.RequireSuper(), auditHandler.List)
+			}
+
+			// 登录锁定（连续登录失败）
+			loginLockHandler := NewLoginLockHandler(deps.AuthService)
+			adminRoutes.GET("/users/:id/login-lock", adminAuth.RequireAdmin(), loginLockHandler.GetLoginLock)
+			adminRoutes.DELETE("/users/:id/login-lock", adminAuth.RequireAdmin(), loginLockHandler.ClearLoginLock)
+
 			// 系统域名管理
 			adminRoutes.GET("/domains", adminAuth.RequireAdmin(), adminHandler.ListSystemDomains)            // 获取域名列表
 			adminRoutes.POST("/domains", adminAuth.RequireSuper(), adminHandler.AddSystemDomain)            // 添加域名
 			adminRoutes.POST("/domains/recover", adminAuth.RequireSuper(), adminHandler.RecoverSystemDomain) // 找回域名
+			adminRoutes.POST("/domains/claim-from-user", adminAuth.RequireSuper(), adminHandler.ClaimDomainFromUser) // 用户域名转系统域名
 			adminRoutes.GET("/domains/:id", adminAuth.RequireAdmin(), adminHandler.GetSystemDomain)          // 获取域名详情
 			adminRoutes.POST("/domains/:id/verify", adminAuth.RequireAdmin(), adminHandler.VerifySystemDomain) // 验证域名
 			adminRoutes.GET("/domains/:id/instructions", adminAuth.RequireAdmin(), adminHandler.GetSystemDomainInstructions) // 配置说明
 			adminRoutes.PATCH("/domains/:id/toggle", adminAuth.RequireAdmin(), adminHandler.ToggleSystemDomainStatus)        // 切换状态
 			adminRoutes.POST("/domains/:id/set-default", adminAuth.RequireSuper(), adminHandler.SetDefaultSystemDomain)      // 设置默认域名
 			adminRoutes.DELETE("/domains/:id", adminAuth.RequireSuper(), adminHandler.DeleteSystemDomain)    // 删除域名
+			adminRoutes.PUT("/domains/:id/public-inboxes", adminAuth.RequireSuper(), adminHandler.SetSystemDomainPublicInboxes) // 允许创建公开收件箱
+			if deps.SinkService != nil {
+				sinkHandler := NewSinkHandler(deps.SinkService)
+				adminRoutes.PUT("/domains/:id/sink", adminAuth.RequireSuper(), sinkHandler.UpdateSystemDomainSink)        // 黑洞模式（超级管理员）
+				adminRoutes.GET("/domains/:id/sink-stats", adminAuth.RequireAdmin(), sinkHandler.GetSystemDomainSinkStats) // 黑洞统计
+			}
 
 			// 系统统计
 			adminRoutes.GET("/statistics", adminAuth.RequireAdmin(), adminHandler.GetStatistics)
@@ -243,6 +587,53 @@ func NewRouter(deps RouterDependencies) *gin.Engine {
 			adminRoutes.GET("/config", adminAuth.RequireAdmin(), configHandler.GetSystemConfig)           // 获取系统配置
 			adminRoutes.PUT("/config", adminAuth.RequireSuper(), configHandler.UpdateSystemConfig)        // 更新系统配置（超级管理员）
 			adminRoutes.POST("/config/reset", adminAuth.RequireSuper(), configHandler.ResetSystemConfig) // 重置系统配置（超级管理员）
+
+			// 维护模式
+			adminRoutes.GET("/maintenance", adminAuth.RequireAdmin(), configHandler.GetMaintenance)  // 获取维护状态
+			adminRoutes.POST("/maintenance", adminAuth.RequireSuper(), configHandler.SetMaintenance) // 切换只读维护模式（超级管理员）
+
+			// JWT 签名密钥轮换
+			if deps.JWTKeyService != nil {
+				jwtKeyHandler := NewJWTKeyHandler(deps.JWTKeyService)
+				adminRoutes.GET("/auth/keys", adminAuth.RequireAdmin(), jwtKeyHandler.GetKeyStatus)              // 密钥状态
+				adminRoutes.POST("/auth/rotate-secret", adminAuth.RequireSuper(), jwtKeyHandler.RotateSecret) // 轮换密钥（超级管理员）
+			}
+
+			// 配置导出/恢复（灾备、搭建预发环境）
+			if deps.BackupService != nil {
+				backupHandler := NewBackupHandler(deps.BackupService)
+				adminRoutes.GET("/backup/settings", adminAuth.RequireAdmin(), backupHandler.ExportSettings)                 // 导出配置（含密钥需超级管理员）
+				adminRoutes.POST("/backup/settings/restore", adminAuth.RequireSuper(), backupHandler.RestoreSettings)    // 恢复配置（超级管理员）
+			}
+			if deps.Snapshotter != nil {
+				snapshotHandler := NewSnapshotHandler(deps.Snapshotter)
+				adminRoutes.GET("/backup/snapshot", adminAuth.RequireSuper(), snapshotHandler.ExportSnapshot) // 导出存储快照（含密码哈希，超级管理员）
+			}
+
+			// 数据保留报告（证明删除时限）
+			if deps.RetentionService != nil {
+				retentionHandler := NewUserDataHandler(deps.UserDataService, deps.RetentionService)
+				adminRoutes.GET("/reports/retention", adminAuth.RequireAdmin(), retentionHandler.RetentionReport)
+			}
+
+			// 排查：最慢的存储调用和 SQL
+			if deps.StoreRecorder != nil {
+				debugHandler := NewDebugHandler(deps.StoreRecorder)
+				adminRoutes.GET("/debug/slow-queries", adminAuth.RequireAdmin(), debugHandler.SlowQueries)
+			}
+
+			// 排查：活跃 SMTP 会话
+			if deps.SMTPSessions != nil {
+				smtpSessionHandler := NewSMTPSessionHandler(deps.SMTPSessions)
+				adminRoutes.GET("/smtp/sessions", adminAuth.RequireAdmin(), smtpSessionHandler.ListSessions)
+				adminRoutes.DELETE("/smtp/sessions/:id", adminAuth.RequireAdmin(), smtpSessionHandler.CloseSession)
+			}
+
+			// 排查：Webhook 目标主机熔断和死信
+			if deps.WebhookService != nil {
+				adminRoutes.GET("/webhooks/breakers", adminAuth.RequireAdmin(), handler.listWebhookBreakers)
+				adminRoutes.GET("/webhooks/dead-letters", adminAuth.RequireAdmin(), handler.listAllWebhookDeadLetters)
+			}
 		}
 
 		// ========== User Domain Routes ==========
@@ -256,19 +647,43 @@ func NewRouter(deps RouterDependencies) *gin.Engine {
 			userDomainRoutes.GET("/:id/instructions", userDomainHandler.GetSetupInstructions) // 配置说明
 			userDomainRoutes.PATCH("/:id", userDomainHandler.UpdateDomainMode)                // 更新模式
 			userDomainRoutes.DELETE("/:id", userDomainHandler.DeleteDomain)                   // 删除域名
+			if deps.StatsService != nil {
+				userDomainRoutes.GET("/:id/stats", handler.domainStats) // 收件统计
+			}
+			if deps.SinkService != nil {
+				sinkHandler := NewSinkHandler(deps.SinkService)
+				userDomainRoutes.PUT("/:id/sink", sinkHandler.UpdateUserDomainSink)          // 黑洞模式
+				userDomainRoutes.GET("/:id/sink-stats", sinkHandler.GetUserDomainSinkStats) // 黑洞统计
+			}
+			if deps.CatchAllService != nil {
+				userDomainRoutes.PUT("/:id/catch-all", NewCatchAllHandler(deps.CatchAllService).UpdateUserDomainCatchAll) // 通配收件
+			}
+			if deps.DomainWhitelist != nil {
+				whitelistHandler := NewDomainWhitelistHandler(deps.DomainWhitelist)
+				userDomainRoutes.GET("/:id/whitelist", whitelistHandler.ListWhitelist)                     // 白名单
+				userDomainRoutes.POST("/:id/whitelist", whitelistHandler.AddWhitelistEntry)                // 添加白名单前缀
+				userDomainRoutes.DELETE("/:id/whitelist/:entryId", whitelistHandler.DeleteWhitelistEntry) // 删除白名单前缀
+			}
 		}
 
+		// ========== Search Routes ==========
+		// 跨邮箱全文搜索（需登录，只搜索本人名下的邮箱）
+		v1.GET("/search", jwtAuth.RequireAuth(), middleware.FeatureUsage(featureRecorder, analytics.FeatureSearch), handler.searchAllMessages)
+
 		// ========== Webhook Routes ==========
 		if deps.WebhookService != nil {
 			webhookRoutes := v1.Group("/webhooks")
 			webhookRoutes.Use(jwtAuth.RequireAuth()) // 需要认证
 			{
-				webhookRoutes.POST("", handler.createWebhook)                       // 创建 Webhook
+				webhookRoutes.POST("", middleware.FeatureUsage(featureRecorder, analytics.FeatureWebhookCreated), handler.createWebhook) // 创建 Webhook
 				webhookRoutes.GET("", handler.listWebhooks)                         // 列出 Webhooks
 				webhookRoutes.GET("/:id", handler.getWebhook)                       // 获取 Webhook
 				webhookRoutes.PATCH("/:id", handler.updateWebhook)                  // 更新 Webhook
 				webhookRoutes.DELETE("/:id", handler.deleteWebhook)                 // 删除 Webhook
 				webhookRoutes.GET("/:id/deliveries", handler.getWebhookDeliveries) // 获取投递记录
+				webhookRoutes.POST("/:id/test", handler.testWebhook)               // 发送示例事件
+				webhookRoutes.GET("/:id/dead-letters", handler.listWebhookDeadLetters) // 列出死信
+				webhookRoutes.POST("/:id/deliveries/:deliveryId/redeliver", handler.redeliverWebhookDelivery) // 重新投递
 			}
 		}
 
@@ -286,9 +701,39 @@ func NewRouter(deps RouterDependencies) *gin.Engine {
 			}
 		}
 
+		// ========== Organization Routes ==========
+		if deps.OrgService != nil {
+			orgHandler := NewOrgHandler(deps.OrgService)
+			orgRoutes := v1.Group("/orgs")
+			orgRoutes.Use(jwtAuth.RequireAuth()) // 需要认证
+			{
+				orgRoutes.POST("", orgHandler.Create)                              // 创建组织
+				orgRoutes.GET("", orgHandler.List)                                 // 我的组织
+				orgRoutes.POST("/join", orgHandler.Join)                           // 凭令牌加入
+				orgRoutes.POST("/:id/invites", orgHandler.Invite)                  // 邀请成员（所有者）
+				orgRoutes.GET("/:id/members", orgHandler.ListMembers)              // 成员列表
+				orgRoutes.DELETE("/:id/members/:userId", orgHandler.RemoveMember) // 移除成员/退出
+			}
+		}
+
+		// ========== Distribution List Routes ==========
+		if deps.DistributionLists != nil {
+			listHandler := NewDistributionListHandler(deps.DistributionLists)
+			listRoutes := v1.Group("/distribution-lists")
+			listRoutes.Use(jwtAuth.RequireAuth()) // 需要认证
+			{
+				listRoutes.POST("", listHandler.Create)                   // 创建分发列表
+				listRoutes.GET("", listHandler.List)                      // 列出分发列表
+				listRoutes.GET("/:id", listHandler.Get)                   // 列表详情
+				listRoutes.PATCH("/:id", listHandler.Update)              // 更新列表
+				listRoutes.DELETE("/:id", listHandler.Delete)             // 删除列表
+				listRoutes.GET("/:id/deliveries", listHandler.Deliveries) // 投递报告
+			}
+		}
+
 		// ========== API Key Routes ==========
 		apiKeyRoutes := v1.Group("/api-keys")
-		apiKeyRoutes.Use(jwtAuth.RequireAuth()) // 所有API Key路由都需要JWT认证
+		apiKeyRoutes.Use(jwtAuth.RequireAuth(), middleware.DenyImpersonation()) // 所有API Key路由都需要JWT认证，代登录令牌不可访问
 		{
 			apiKeyRoutes.POST("", apiKeyHandler.CreateAPIKey)       // 创建API Key
 			apiKeyRoutes.GET("", apiKeyHandler.ListAPIKeys)         // 列出API Keys
@@ -314,8 +759,11 @@ func NewRouter(deps RouterDependencies) *gin.Engine {
 
 type createMailboxRequest struct {
 	Prefix    string `json:"prefix"`
-	Domain    string `json:"domain"`
-	ExpiresIn string `json:"expiresIn"`
+	Domain    string  `json:"domain"`
+	ExpiresIn string  `json:"expiresIn"`
+	OrgID     *string `json:"orgId"` // 可选：创建为组织邮箱（需登录且为组织成员）
+	Public    bool    `json:"public"` // 可选：创建为公开收件箱（仅限允许公开收件箱的域名）
+	AutoRenew bool    `json:"autoRenew"` // 可选：开启自动续期（需登录）
 }
 
 type mailboxResponse struct {
@@ -324,10 +772,25 @@ type mailboxResponse struct {
 	LocalPart string     `json:"localPart"`
 	Domain    string     `json:"domain"`
 	Token     string     `json:"token"`
+	OrgID     *string    `json:"orgId,omitempty"`
 	CreatedAt time.Time  `json:"createdAt"`
 	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 	Unread    int        `json:"unread"`
 	Total     int        `json:"total"`
+	// 自定义垃圾邮件阈值（未设置时使用系统配置）
+	SpamQuarantineScore *float64 `json:"spamQuarantineScore,omitempty"`
+	SpamRejectScore     *float64 `json:"spamRejectScore,omitempty"`
+	// 最近一次访问时间与闲置标记（超过闲置策略时长未访问）
+	LastAccessedAt *time.Time `json:"lastAccessedAt,omitempty"`
+	Idle           bool       `json:"idle"`
+	// 公开收件箱：无需令牌即可只读查看
+	IsPublic bool `json:"isPublic"`
+	// 自动续期：临近过期时按默认有效期续期，直到用户等级的最长生存时间
+	AutoRenew bool `json:"autoRenew"`
+	// 摘要列表才有：最近一封邮件预览、最近活动时间和邮件占用字节
+	LastMessage    *domain.MessagePreview `json:"lastMessage,omitempty"`
+	LastActivityAt *time.Time             `json:"lastActivityAt,omitempty"`
+	StorageBytes   *int64                 `json:"storageBytes,omitempty"`
 }
 
 type mailboxListResponse struct {
@@ -335,6 +798,32 @@ type mailboxListResponse struct {
 	Count int               `json:"count"`
 }
 
+// ReasonAddressTaken 地址已被其他邮箱或别名占用
+const ReasonAddressTaken = "ADDRESS_TAKEN"
+
+// respondAddressTaken 地址冲突时返回 409 和结构化原因
+func respondAddressTaken(c *gin.Context) {
+	c.JSON(http.StatusConflict, Response{
+		Code: CodeConflict,
+		Msg:  GetErrorMessage(service.ErrAddressTaken),
+		Data: gin.H{"reason": ReasonAddressTaken},
+	})
+}
+
+// respondQuotaError 输出邮箱数量、邮件数量配额用尽（403，带 X-Quota-* 响应头），返回是否已处理
+func respondQuotaError(c *gin.Context, err error) bool {
+	var quota *service.QuotaError
+	if !errors.As(err, &quota) {
+		return false
+	}
+	middleware.RespondQuotaExceeded(c, middleware.QuotaUsage{
+		Scope: quota.Scope,
+		Limit: quota.Limit,
+		Used:  quota.Used,
+	}, GetErrorMessage(quota.Err))
+	return true
+}
+
 // createMailbox godoc
 // @Summary 创建临时邮箱
 // @Description 创建一个新的临时邮箱地址
@@ -344,6 +833,7 @@ type mailboxListResponse struct {
 // @Param request body createMailboxRequest true "邮箱参数"
 // @Success 201 {object} mailboxResponse
 // @Failure 400 {object} Response
+// @Failure 409 {object} Response "地址已被占用（data.reason=ADDRESS_TAKEN）"
 // @Failure 500 {object} Response
 // @Router /v1/mailboxes [post]
 func (h *Handler) createMailbox(c *gin.Context) {
@@ -372,17 +862,27 @@ func (h *Handler) createMailbox(c *gin.Context) {
 		}
 	}
 
-	mailbox, err := h.mailboxes.Create(service.CreateMailboxInput{
+	mailbox, err := h.mailboxes.Create(c.Request.Context(), service.CreateMailboxInput{
 		Prefix:    req.Prefix,
 		Domain:    req.Domain,
 		IPSource:  c.ClientIP(),
 		UserID:    userID, // 关联用户ID（游客模式为nil）
+		OrgID:     req.OrgID,
 		ExpiresAt: expiresAt,
+		Public:    req.Public,
+		AutoRenew: req.AutoRenew,
 	})
 	if err != nil {
+		if respondOrgError(c, err) || respondQuotaError(c, err) {
+			return
+		}
 		switch err {
 		case service.ErrDomainNotAllowed, service.ErrPrefixInvalid:
 			BadRequest(c, GetErrorMessage(err))
+		case service.ErrDomainExpired, service.ErrPublicInboxNotAllowed, service.ErrAddressNotWhitelisted, service.ErrPrefixReserved, service.ErrRenewRequiresAccount:
+			Forbidden(c, GetErrorMessage(err))
+		case service.ErrAddressTaken:
+			respondAddressTaken(c)
 		default:
 			InternalError(c, MsgMailboxCreateFailed)
 		}
@@ -394,10 +894,14 @@ func (h *Handler) createMailbox(c *gin.Context) {
 
 // listMailboxes godoc
 // @Summary 获取邮箱列表
-// @Description 返回当前用户的临时邮箱列表（认证用户）或所有邮箱（游客）
+// @Description 返回当前用户的临时邮箱列表（认证用户）或所有邮箱（游客）。
+// @Description 认证用户默认返回摘要：按邮件实时统计的数量、最近邮件预览和最近活动时间
 // @Tags Mailboxes
 // @Produce json
+// @Param summary query bool false "认证用户是否返回摘要（默认 true，false 时只返回邮箱记录）"
+// @Param sort query string false "摘要排序：lastActivity（默认）、createdAt、address"
 // @Success 200 {object} mailboxListResponse
+// @Failure 400 {object} Response
 // @Router /v1/mailboxes [get]
 func (h *Handler) listMailboxes(c *gin.Context) {
 	var mailboxes []domain.Mailbox
@@ -405,13 +909,17 @@ func (h *Handler) listMailboxes(c *gin.Context) {
 	// 如果用户已认证，只返回该用户的邮箱
 	if userIDVal, exists := c.Get("userID"); exists {
 		if userID, ok := userIDVal.(string); ok {
-			mailboxes = h.mailboxes.ListByUserID(userID)
+			if c.Query("summary") != "false" {
+				h.listMailboxSummaries(c, userID)
+				return
+			}
+			mailboxes = h.mailboxes.ListByUserID(c.Request.Context(), userID)
 		} else {
-			mailboxes = h.mailboxes.List()
+			mailboxes = h.mailboxes.List(c.Request.Context())
 		}
 	} else {
 		// 未认证用户：返回所有邮箱（包括游客邮箱）
-		mailboxes = h.mailboxes.List()
+		mailboxes = h.mailboxes.List(c.Request.Context())
 	}
 
 	responses := make([]mailboxResponse, 0, len(mailboxes))
@@ -425,6 +933,28 @@ func (h *Handler) listMailboxes(c *gin.Context) {
 	})
 }
 
+// listMailboxSummaries 返回认证用户的邮箱摘要列表
+func (h *Handler) listMailboxSummaries(c *gin.Context, userID string) {
+	summaries, err := h.mailboxes.ListSummariesByUserID(c.Request.Context(), userID, c.Query("sort"))
+	if err != nil {
+		if err == service.ErrInvalidMailboxSort {
+			BadRequest(c, GetErrorMessage(err))
+			return
+		}
+		InternalError(c, MsgInternalError)
+		return
+	}
+
+	responses := make([]mailboxResponse, 0, len(summaries))
+	for i := range summaries {
+		responses = append(responses, toMailboxSummaryResponse(&summaries[i]))
+	}
+	Success(c, mailboxListResponse{
+		Items: responses,
+		Count: len(responses),
+	})
+}
+
 // getMailbox godoc
 // @Summary 获取邮箱详情
 // @Description 根据邮箱 ID 查看详细信息
@@ -442,6 +972,60 @@ func (h *Handler) getMailbox(c *gin.Context) {
 	Success(c, toMailboxResponse(mailbox))
 }
 
+// updateMailboxRequest 更新邮箱设置请求（字段为空表示不修改）
+type updateMailboxRequest struct {
+	SpamQuarantineScore *float64 `json:"spamQuarantineScore"` // 软阈值，0 表示恢复系统默认
+	SpamRejectScore     *float64 `json:"spamRejectScore"`     // 硬阈值，0 表示恢复系统默认
+	AutoRenew           *bool    `json:"autoRenew"`           // 自动续期（仅用户邮箱）
+}
+
+// updateMailbox godoc
+// @Summary 更新邮箱设置
+// @Description 设置邮箱的垃圾邮件阈值（如 QA 邮箱调高容忍度），不能超过系统配置的上限；开启或关闭自动续期（仅用户邮箱）
+// @Tags Mailboxes
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param request body updateMailboxRequest true "邮箱设置"
+// @Success 200 {object} mailboxResponse
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/mailboxes/{id} [patch]
+func (h *Handler) updateMailbox(c *gin.Context) {
+	var req updateMailboxRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.SpamQuarantineScore == nil && req.SpamRejectScore == nil && req.AutoRenew == nil) {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	var mailbox *domain.Mailbox
+	var err error
+	if req.SpamQuarantineScore != nil || req.SpamRejectScore != nil {
+		mailbox, err = h.mailboxes.UpdateSpamThresholds(c.Request.Context(), c.Param("id"), service.UpdateSpamThresholdsInput{
+			QuarantineScore: req.SpamQuarantineScore,
+			RejectScore:     req.SpamRejectScore,
+		})
+	}
+	if err == nil && req.AutoRenew != nil {
+		mailbox, err = h.mailboxes.SetAutoRenew(c.Request.Context(), c.Param("id"), *req.AutoRenew)
+	}
+	if err != nil {
+		switch err {
+		case service.ErrSpamThresholdInvalid:
+			BadRequest(c, GetErrorMessage(err))
+		case service.ErrRenewRequiresAccount:
+			Forbidden(c, GetErrorMessage(err))
+		case memory.ErrMailboxNotFound:
+			NotFound(c, MsgMailboxNotFound)
+		default:
+			InternalError(c, MsgInternalError)
+		}
+		return
+	}
+
+	Success(c, toMailboxResponse(mailbox))
+}
+
 // deleteMailbox godoc
 // @Summary 删除临时邮箱
 // @Description 删除指定 ID 的邮箱及其邮件
@@ -453,7 +1037,7 @@ func (h *Handler) getMailbox(c *gin.Context) {
 // @Router /v1/mailboxes/{id} [delete]
 func (h *Handler) deleteMailbox(c *gin.Context) {
 	mailboxID := c.Param("id")
-	err := h.mailboxes.Delete(mailboxID)
+	err := h.mailboxes.Delete(c.Request.Context(), mailboxID)
 	if err != nil {
 		if err == memory.ErrMailboxNotFound {
 			NotFound(c, MsgMailboxNotFound)
@@ -478,27 +1062,50 @@ type createMessageRequest struct {
 type attachmentInfo struct {
 	ID          string `json:"id"`
 	Filename    string `json:"filename"`
-	ContentType string `json:"contentType"`
+	ContentType string `json:"contentType"` // 发件方声明的类型
 	Size        int64  `json:"size"`
+	// 按内容判断的实际类型；与声明类型存在安全相关的不一致时 contentTypeMismatch 为 true，前端应提示
+	DetectedContentType string `json:"detectedContentType,omitempty"`
+	ContentTypeMismatch bool   `json:"contentTypeMismatch"`
+	// 病毒扫描检出威胁时 infected 为 true，附件禁止下载
+	Infected bool   `json:"infected"`
+	Threat   string `json:"threat,omitempty"`
 }
 
 type messageResponse struct {
 	ID          string           `json:"id"`
 	MailboxID   string           `json:"mailboxId"`
+	Seq         int64            `json:"seq"` // 邮箱内入库序号（列表按序号倒序）
 	From        string           `json:"from"`
 	To          string           `json:"to"`
 	Subject     string           `json:"subject"`
 	Text        string           `json:"text"`
 	HTML        string           `json:"html"`
+	Language    string           `json:"detectedLanguage,omitempty"` // 检测到的正文语言（ISO 639-1）
+	SpamScore   float64          `json:"spamScore"`                  // 垃圾邮件评分（未评分时为 0）
+	SpamAction  string           `json:"spamAction,omitempty"`
+	SpamSymbols []string         `json:"spamSymbols,omitempty"`
+	Quarantined bool             `json:"quarantined"` // 是否在隔离区
+	IsSpam      bool             `json:"isSpam"`      // 是否判定为垃圾邮件
+	DKIMResult  string           `json:"dkimResult,omitempty"` // DKIM 验证结果：pass、fail（发件人可能被伪造）或 none
+	DKIMDomain  string           `json:"dkimDomain,omitempty"` // DKIM 签名域名
 	IsRead      bool             `json:"isRead"`
 	CreatedAt   time.Time        `json:"createdAt"`
 	ReceivedAt  time.Time        `json:"receivedAt"`
 	Attachments []attachmentInfo `json:"attachments,omitempty"` // 附件列表（不包含内容）
+
+	CapturedHeaders map[string]string             `json:"capturedHeaders,omitempty"` // 收信时按名单保存的头
+	AuthResults     *domain.AuthenticationResults `json:"authResults,omitempty"`     // 发件人认证结果（SPF、DKIM、DMARC）
+	ExpiresAt       *time.Time                    `json:"expiresAt,omitempty"`       // 按到期规则删除的时间（没有命中规则时为空）
+	Verification    *domain.Verification          `json:"verification,omitempty"`    // 入库时提取的验证码和验证链接
 }
 
 type messageListResponse struct {
-	Items []messageResponse `json:"items"`
-	Count int               `json:"count"`
+	Items   []messageResponse `json:"items"`
+	Count   int               `json:"count"`   // 本页数量
+	Total   int               `json:"total"`   // 符合条件的邮件总数
+	Offset  int               `json:"offset"`  // 本页起始位置
+	HasMore bool              `json:"hasMore"` // 之后是否还有邮件
 }
 
 // createMessage godoc
@@ -522,7 +1129,7 @@ func (h *Handler) createMessage(c *gin.Context) {
 	}
 
 	mailboxID := c.Param("id")
-	message, err := h.messages.Create(service.CreateMessageInput{
+	input := service.CreateMessageInput{
 		MailboxID: mailboxID,
 		From:      req.From,
 		To:        req.To,
@@ -531,7 +1138,15 @@ func (h *Handler) createMessage(c *gin.Context) {
 		HTML:      req.HTML,
 		Raw:       req.Raw,
 		IsRead:    req.IsRead,
-	})
+	}
+	if value, ok := c.Get("mailbox"); ok {
+		mailbox := value.(*domain.Mailbox)
+		input.ExpiryRules = mailbox.ExpiryRules
+		if respondQuotaError(c, h.mailboxes.CheckMessageQuota(c.Request.Context(), mailbox)) {
+			return
+		}
+	}
+	message, err := h.messages.Create(c.Request.Context(), input)
 	if err != nil {
 		if err == memory.ErrMailboxNotFound {
 			NotFound(c, MsgMailboxNotFound)
@@ -546,37 +1161,110 @@ func (h *Handler) createMessage(c *gin.Context) {
 
 // listMessages godoc
 // @Summary 获取邮件列表
-// @Description 返回邮箱内的全部邮件
+// @Description 分页返回邮箱内的邮件（quarantined=true 时返回隔离区中的邮件）。isSpam 按垃圾邮件判定过滤（判定为垃圾的邮件在隔离区中）。header.<名称>=<值> 按收信时保存的头精确过滤，如 header.X-Test-Run-ID=4711
 // @Tags Messages
 // @Produce json
 // @Param id path string true "邮箱ID"
+// @Param quarantined query boolean false "是否查看隔离区"
+// @Param isSpam query boolean false "是否判定为垃圾邮件"
+// @Param unreadOnly query boolean false "只返回未读邮件"
+// @Param sort query string false "排序字段：receivedAt（默认）、subject、from"
+// @Param order query string false "排序方向：desc（默认）或 asc"
+// @Param limit query int false "每页数量（默认 50，最多 200）"
+// @Param offset query int false "起始位置（默认 0）"
 // @Success 200 {object} messageListResponse
+// @Failure 400 {object} Response
 // @Failure 404 {object} Response
 // @Failure 500 {object} Response
 // @Router /v1/mailboxes/{id}/messages [get]
 func (h *Handler) listMessages(c *gin.Context) {
-	messages, err := h.messages.List(c.Param("id"))
+	headers, ok := headerFilters(c)
+	if !ok {
+		BadRequest(c, MsgInvalidHeaderFilter)
+		return
+	}
+	input := service.ListPageInput{
+		MailboxID:   c.Param("id"),
+		Quarantined: c.Query("quarantined") == "true",
+		Headers:     headers,
+		Sort:        c.Query("sort"),
+		Order:       c.Query("order"),
+	}
+	if raw := c.Query("isSpam"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+		input.IsSpam = &value
+	}
+	if raw := c.Query("unreadOnly"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+		input.UnreadOnly = value
+	}
+	for param, target := range map[string]*int{"limit": &input.Limit, "offset": &input.Offset} {
+		if raw := c.Query(param); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				BadRequest(c, GetErrorMessage(service.ErrInvalidMessagePage))
+				return
+			}
+			*target = value
+		}
+	}
+
+	page, err := h.messages.ListPage(c.Request.Context(), input)
 	if err != nil {
-		if err == memory.ErrMailboxNotFound {
+		switch {
+		case errors.Is(err, service.ErrInvalidMessageSort), errors.Is(err, service.ErrInvalidMessagePage):
+			BadRequest(c, GetErrorMessage(err))
+		case errors.Is(err, memory.ErrMailboxNotFound):
 			NotFound(c, MsgMailboxNotFound)
-			return
+		default:
+			InternalError(c, MsgMessageListFailed)
 		}
-		InternalError(c, MsgMessageListFailed)
 		return
 	}
 
-	responses := make([]messageResponse, 0, len(messages))
-	for i := range messages {
-		msg := messages[i]
-		responses = append(responses, toMessageResponse(&msg))
+	h.touchMailbox(c.Request.Context(), c.Param("id"))
+
+	responses := make([]messageResponse, 0, len(page.Messages))
+	for i := range page.Messages {
+		responses = append(responses, toMessageResponse(&page.Messages[i]))
 	}
 
 	Success(c, messageListResponse{
-		Items: responses,
-		Count: len(responses),
+		Items:   responses,
+		Count:   len(responses),
+		Total:   page.Total,
+		Offset:  input.Offset,
+		HasMore: input.Offset+len(responses) < page.Total,
 	})
 }
 
+// headerFilters 解析 header.<名称>=<值> 查询参数（名称不区分大小写），名称无效时返回 false
+func headerFilters(c *gin.Context) (map[string]string, bool) {
+	var filters map[string]string
+	for key, values := range c.Request.URL.Query() {
+		name, ok := strings.CutPrefix(key, "header.")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if !domain.ValidHeaderName(name) {
+			return nil, false
+		}
+		if filters == nil {
+			filters = make(map[string]string)
+		}
+		filters[domain.CanonicalHeaderName(name)] = values[0]
+	}
+	return filters, true
+}
+
 // getMessage godoc
 // @Summary 获取邮件详情
 // @Description 查看单封邮件内容
@@ -589,7 +1277,7 @@ func (h *Handler) listMessages(c *gin.Context) {
 // @Failure 500 {object} Response
 // @Router /v1/mailboxes/{id}/messages/{messageId} [get]
 func (h *Handler) getMessage(c *gin.Context) {
-	msg, err := h.messages.Get(c.Param("id"), c.Param("messageId"))
+	msg, err := h.messages.Get(c.Request.Context(), c.Param("id"), c.Param("messageId"))
 	if err != nil {
 		if err == memory.ErrMessageNotFound {
 			NotFound(c, MsgMessageNotFound)
@@ -599,6 +1287,7 @@ func (h *Handler) getMessage(c *gin.Context) {
 		return
 	}
 
+	h.touchMailbox(c.Request.Context(), c.Param("id"))
 	Success(c, toMessageResponse(msg))
 }
 
@@ -613,7 +1302,7 @@ func (h *Handler) getMessage(c *gin.Context) {
 // @Failure 500 {object} Response
 // @Router /v1/mailboxes/{id}/messages/{messageId}/read [post]
 func (h *Handler) markMessageRead(c *gin.Context) {
-	err := h.messages.MarkRead(c.Param("id"), c.Param("messageId"))
+	err := h.messages.MarkRead(c.Request.Context(), c.Param("id"), c.Param("messageId"))
 	if err != nil {
 		if err == memory.ErrMessageNotFound {
 			NotFound(c, MsgMessageNotFound)
@@ -625,6 +1314,56 @@ func (h *Handler) markMessageRead(c *gin.Context) {
 	NoContent(c)
 }
 
+// deleteMessage godoc
+// @Summary 删除邮件
+// @Description 删除指定邮件及其附件，并通过 WebSocket 推送 mailbox_update
+// @Tags Messages
+// @Param id path string true "邮箱ID"
+// @Param messageId path string true "邮件ID"
+// @Success 204
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /v1/mailboxes/{id}/messages/{messageId} [delete]
+func (h *Handler) deleteMessage(c *gin.Context) {
+	err := h.messages.Remove(c.Request.Context(), c.Param("id"), c.Param("messageId"))
+	if err != nil {
+		if err == memory.ErrMessageNotFound {
+			NotFound(c, MsgMessageNotFound)
+		} else {
+			InternalError(c, MsgMessageDeleteFailed)
+		}
+		return
+	}
+	NoContent(c)
+}
+
+type clearMessagesResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// clearMessages godoc
+// @Summary 清空邮箱
+// @Description 删除邮箱中的所有邮件，返回删除数量，有邮件被删除时通过 WebSocket 推送 mailbox_update
+// @Tags Messages
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Success 200 {object} clearMessagesResponse
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /v1/mailboxes/{id}/messages [delete]
+func (h *Handler) clearMessages(c *gin.Context) {
+	count, err := h.messages.RemoveAll(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if err == memory.ErrMailboxNotFound {
+			NotFound(c, MsgMailboxNotFound)
+		} else {
+			InternalError(c, MsgMessageDeleteFailed)
+		}
+		return
+	}
+	Success(c, clearMessagesResponse{Deleted: count})
+}
+
 // toMailboxResponse 转换实体为响应体。
 func toMailboxResponse(mailbox *domain.Mailbox) mailboxResponse {
 	return mailboxResponse{
@@ -633,10 +1372,38 @@ func toMailboxResponse(mailbox *domain.Mailbox) mailboxResponse {
 		LocalPart: mailbox.LocalPart,
 		Domain:    mailbox.Domain,
 		Token:     mailbox.Token,
+		OrgID:     mailbox.OrgID,
 		CreatedAt: mailbox.CreatedAt,
 		ExpiresAt: mailbox.ExpiresAt,
 		Unread:    mailbox.Unread,
 		Total:     mailbox.TotalCount,
+
+		SpamQuarantineScore: mailbox.SpamQuarantineScore,
+		SpamRejectScore:     mailbox.SpamRejectScore,
+
+		LastAccessedAt: mailbox.LastAccessedAt,
+		Idle:           mailbox.IdleSince != nil,
+
+		IsPublic:  mailbox.IsPublic,
+		AutoRenew: mailbox.AutoRenew,
+	}
+}
+
+// toMailboxSummaryResponse 转换邮箱摘要为响应体（数量使用实时统计值）
+func toMailboxSummaryResponse(summary *domain.MailboxSummary) mailboxResponse {
+	resp := toMailboxResponse(&summary.Mailbox)
+	lastActivity := summary.LastActivityAt()
+	storageBytes := summary.StorageBytes
+	resp.LastMessage = summary.LastMessage
+	resp.LastActivityAt = &lastActivity
+	resp.StorageBytes = &storageBytes
+	return resp
+}
+
+// touchMailbox 记录邮箱被访问（尽力而为，失败不影响请求）
+func (h *Handler) touchMailbox(ctx context.Context, mailboxID string) {
+	if h.idle != nil {
+		_ = h.idle.Touch(ctx, mailboxID)
 	}
 }
 
@@ -650,18 +1417,37 @@ func toMessageResponse(message *domain.Message) messageResponse {
 			Filename:    att.Filename,
 			ContentType: att.ContentType,
 			Size:        att.Size,
+
+			DetectedContentType: att.DetectedContentType,
+			ContentTypeMismatch: att.ContentTypeMismatch,
+			Infected:            att.Infected,
+			Threat:              att.Threat,
 		})
 	}
 
 	return messageResponse{
 		ID:          message.ID,
 		MailboxID:   message.MailboxID,
+		Seq:         message.Seq,
 		From:        message.From,
 		To:          message.To,
 		Subject:     message.Subject,
 		Text:        message.Text,
 		HTML:        message.HTML,
+		Language:    message.DetectedLanguage,
+		SpamScore:   message.SpamScore,
+		SpamAction:  message.SpamAction,
+		SpamSymbols: message.SpamSymbols,
+		Quarantined: message.Quarantined,
+		IsSpam:      message.IsSpam,
+		DKIMResult:  message.DKIMResult,
+		DKIMDomain:  message.DKIMDomain,
 		IsRead:      message.IsRead,
+
+		CapturedHeaders: message.CapturedHeaders,
+		AuthResults:     message.AuthResults,
+		ExpiresAt:       message.ExpiresAt,
+		Verification:    message.Verification,
 		CreatedAt:   message.CreatedAt,
 		ReceivedAt:  message.ReceivedAt,
 		Attachments: attachments,
@@ -670,13 +1456,16 @@ func toMessageResponse(message *domain.Message) messageResponse {
 
 // downloadAttachment godoc
 // @Summary 下载附件
-// @Description 下载邮件的附件文件
+// @Description 下载邮件的附件文件（病毒扫描检出威胁的附件禁止下载）
 // @Tags Messages
 // @Produce application/octet-stream
 // @Param id path string true "邮箱ID"
 // @Param messageId path string true "邮件ID"
 // @Param attachmentId path string true "附件ID"
+// @Param Range header string false "字节范围（如 bytes=0-1048575），用于断点续传"
 // @Success 200 {file} binary
+// @Success 206 {file} binary "部分内容"
+// @Failure 403 {object} Response "附件检出病毒"
 // @Failure 404 {object} Response
 // @Failure 500 {object} Response
 // @Router /v1/mailboxes/{id}/messages/{messageId}/attachments/{attachmentId} [get]
@@ -686,7 +1475,7 @@ func (h *Handler) downloadAttachment(c *gin.Context) {
 	attachmentID := c.Param("attachmentId")
 
 	// 获取附件
-	attachment, err := h.messages.GetAttachment(mailboxID, messageID, attachmentID)
+	attachment, err := h.messages.GetAttachment(c.Request.Context(), mailboxID, messageID, attachmentID)
 	if err != nil {
 		if err == memory.ErrMessageNotFound {
 			NotFound(c, MsgMessageNotFound)
@@ -695,12 +1484,20 @@ func (h *Handler) downloadAttachment(c *gin.Context) {
 		NotFound(c, MsgAttachmentNotFound)
 		return
 	}
+	if attachment.Infected {
+		Forbidden(c, GetErrorMessage(service.ErrAttachmentInfected))
+		return
+	}
+
+	content, err := attachment.Open()
+	if err != nil {
+		InternalError(c, MsgAttachmentNotFound)
+		return
+	}
+	defer content.Close()
 
-	// 附件下载不使用统一响应格式，直接返回二进制流
-	c.Header("Content-Type", attachment.ContentType)
-	c.Header("Content-Disposition", "attachment; filename=\""+attachment.Filename+"\"")
-	c.Header("Content-Length", fmt.Sprintf("%d", attachment.Size))
-	c.Data(http.StatusOK, attachment.ContentType, attachment.Content)
+	// 附件下载不使用统一响应格式，直接返回二进制流（大附件从存储流式读取，支持 Range）
+	serveAttachment(c, attachment, content)
 }
 
 // searchMessages godoc
@@ -717,8 +1514,16 @@ func (h *Handler) downloadAttachment(c *gin.Context) {
 // @Param endDate query string false "结束日期 (RFC3339格式)"
 // @Param isRead query boolean false "是否已读"
 // @Param hasAttachment query boolean false "是否有附件"
+// @Param language query string false "正文语言（ISO 639-1，如 de）"
+// @Param spamScoreGte query number false "垃圾邮件评分下限（含）"
+// @Param isSpam query boolean false "是否判定为垃圾邮件"
+// @Param dkimResult query string false "DKIM 验证结果（pass、fail 或 none），如 fail 查找可能伪造发件人的邮件"
+// @Param header.{name} query string false "按收信时保存的头精确过滤，如 header.X-Test-Run-ID=4711"
 // @Param page query int false "页码（默认1）"
 // @Param pageSize query int false "每页数量（默认20，最大100）"
+// @Param highlight query boolean false "是否返回命中摘要（默认true）"
+// @Param highlightPre query string false "命中前标记（默认<em>，可选 em、mark、b、strong、span，不带属性）"
+// @Param highlightPost query string false "命中后标记（默认与命中前标记配对的闭合标签）"
 // @Success 200 {object} Response{data=domain.MessageSearchResult}
 // @Failure 400 {object} Response
 // @Failure 500 {object} Response
@@ -728,15 +1533,22 @@ func (h *Handler) searchMessages(c *gin.Context) {
 
 	// 解析查询参数
 	var input struct {
-		Query         string `form:"q"`
-		From          string `form:"from"`
-		Subject       string `form:"subject"`
-		StartDate     string `form:"startDate"`
-		EndDate       string `form:"endDate"`
-		IsRead        *bool  `form:"isRead"`
-		HasAttachment *bool  `form:"hasAttachment"`
-		Page          int    `form:"page"`
-		PageSize      int    `form:"pageSize"`
+		Query         string   `form:"q"`
+		From          string   `form:"from"`
+		Subject       string   `form:"subject"`
+		StartDate     string   `form:"startDate"`
+		EndDate       string   `form:"endDate"`
+		IsRead        *bool    `form:"isRead"`
+		HasAttachment *bool    `form:"hasAttachment"`
+		Language      string   `form:"language"`
+		SpamScoreGte  *float64 `form:"spamScoreGte"`
+		IsSpam        *bool    `form:"isSpam"`
+		DKIMResult    string   `form:"dkimResult"`
+		Page          int      `form:"page"`
+		PageSize      int      `form:"pageSize"`
+		Highlight     *bool    `form:"highlight"`
+		HighlightPre  string   `form:"highlightPre"`
+		HighlightPost string   `form:"highlightPost"`
 	}
 
 	if err := c.ShouldBindQuery(&input); err != nil {
@@ -745,6 +1557,19 @@ func (h *Handler) searchMessages(c *gin.Context) {
 		})
 		return
 	}
+	headers, ok := headerFilters(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse{
+			Error: MsgInvalidHeaderFilter,
+		})
+		return
+	}
+	if input.DKIMResult != "" && !domain.ValidDKIMResult(input.DKIMResult) {
+		c.JSON(http.StatusBadRequest, errorResponse{
+			Error: MsgInvalidDKIMResult,
+		})
+		return
+	}
 
 	// 解析时间参数
 	var startDate, endDate *time.Time
@@ -769,8 +1594,21 @@ func (h *Handler) searchMessages(c *gin.Context) {
 		endDate = &t
 	}
 
+	// 高亮选项（默认开启，highlight=false 关闭）
+	var highlight *domain.HighlightOptions
+	if input.Highlight == nil || *input.Highlight {
+		opts, err := domain.ParseHighlightOptions(input.HighlightPre, input.HighlightPost)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse{
+				Error: "高亮标记只能是 <em>、<mark>、<b>、<strong> 或 <span>，且不能带属性",
+			})
+			return
+		}
+		highlight = &opts
+	}
+
 	// 执行搜索
-	result, err := h.search.SearchMessages(service.SearchMessagesInput{
+	result, err := h.search.SearchMessages(c.Request.Context(), service.SearchMessagesInput{
 		MailboxID:     mailboxID,
 		Query:         input.Query,
 		From:          input.From,
@@ -779,8 +1617,14 @@ func (h *Handler) searchMessages(c *gin.Context) {
 		EndDate:       endDate,
 		IsRead:        input.IsRead,
 		HasAttachment: input.HasAttachment,
+		Language:      input.Language,
+		SpamScoreGte:  input.SpamScoreGte,
+		IsSpam:        input.IsSpam,
+		DKIMResult:    input.DKIMResult,
+		Headers:       headers,
 		Page:          input.Page,
 		PageSize:      input.PageSize,
+		Highlight:     highlight,
 	})
 
 	if err != nil {
@@ -806,6 +1650,7 @@ func (h *Handler) searchMessages(c *gin.Context) {
 // @Success 201 {object} domain.MailboxAlias
 // @Failure 400 {object} Response
 // @Failure 404 {object} Response
+// @Failure 409 {object} Response "地址已被邮箱占用（data.reason=ADDRESS_TAKEN）"
 // @Failure 500 {object} Response
 // @Router /v1/mailboxes/{id}/aliases [post]
 func (h *Handler) createAlias(c *gin.Context) {
@@ -820,12 +1665,16 @@ func (h *Handler) createAlias(c *gin.Context) {
 		return
 	}
 
-	alias, err := h.aliases.Create(service.CreateAliasInput{
+	alias, err := h.aliases.Create(c.Request.Context(), service.CreateAliasInput{
 		MailboxID: mailboxID,
 		Address:   req.Address,
 	})
 
 	if err != nil {
+		if errors.Is(err, service.ErrAddressTaken) {
+			respondAddressTaken(c)
+			return
+		}
 		BadRequest(c, err.Error())
 		return
 	}
@@ -846,7 +1695,7 @@ func (h *Handler) createAlias(c *gin.Context) {
 func (h *Handler) listAliases(c *gin.Context) {
 	mailboxID := c.Param("id")
 
-	aliases, err := h.aliases.List(mailboxID)
+	aliases, err := h.aliases.List(c.Request.Context(), mailboxID)
 	if err != nil {
 		InternalError(c, MsgAliasListFailed)
 		return
@@ -872,7 +1721,7 @@ func (h *Handler) listAliases(c *gin.Context) {
 func (h *Handler) getAlias(c *gin.Context) {
 	aliasID := c.Param("aliasId")
 
-	alias, err := h.aliases.Get(aliasID)
+	alias, err := h.aliases.Get(c.Request.Context(), aliasID)
 	if err != nil {
 		NotFound(c, MsgAliasNotFound)
 		return
@@ -896,7 +1745,7 @@ func (h *Handler) deleteAlias(c *gin.Context) {
 	mailboxID := c.Param("id")
 	aliasID := c.Param("aliasId")
 
-	if err := h.aliases.Delete(mailboxID, aliasID); err != nil {
+	if err := h.aliases.Delete(c.Request.Context(), mailboxID, aliasID); err != nil {
 		InternalError(c, MsgAliasDeleteFailed)
 		return
 	}
@@ -931,13 +1780,13 @@ func (h *Handler) toggleAlias(c *gin.Context) {
 		return
 	}
 
-	if err := h.aliases.Toggle(mailboxID, aliasID, req.IsActive); err != nil {
+	if err := h.aliases.Toggle(c.Request.Context(), mailboxID, aliasID, req.IsActive); err != nil {
 		InternalError(c, MsgAliasToggleFailed)
 		return
 	}
 
 	// 返回更新后的别名
-	alias, err := h.aliases.Get(aliasID)
+	alias, err := h.aliases.Get(c.Request.Context(), aliasID)
 	if err != nil {
 		NotFound(c, MsgAliasNotFound)
 		return
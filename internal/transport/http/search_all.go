@@ -0,0 +1,64 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// searchAllMessages godoc
+// @Summary 跨邮箱全文搜索
+// @Description 通过全文索引在当前用户的所有邮箱中搜索邮件（主题、发件人、纯文本和 HTML 正文），所有关键词都需命中，按接收时间倒序。
+// @Description 中日韩文字逐字匹配；隔离区中的邮件不返回。
+// @Tags Messages
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "搜索关键词"
+// @Param page query int false "页码（默认1）"
+// @Param pageSize query int false "每页数量（默认20，最大100）"
+// @Param highlight query boolean false "是否返回命中摘要（默认true）"
+// @Success 200 {object} Response{data=domain.MessageSearchResult}
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 503 {object} Response
+// @Router /v1/search [get]
+func (h *Handler) searchAllMessages(c *gin.Context) {
+	var input struct {
+		Query     string `form:"q"`
+		Page      int    `form:"page"`
+		PageSize  int    `form:"pageSize"`
+		Highlight *bool  `form:"highlight"`
+	}
+	if err := c.ShouldBindQuery(&input); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	var highlight *domain.HighlightOptions
+	if input.Highlight == nil || *input.Highlight {
+		opts := domain.DefaultHighlightOptions()
+		highlight = &opts
+	}
+
+	result, err := h.search.SearchAll(c.Request.Context(), service.SearchAllInput{
+		UserID:    c.GetString("userID"),
+		Query:     input.Query,
+		Page:      input.Page,
+		PageSize:  input.PageSize,
+		Highlight: highlight,
+	})
+	switch {
+	case err == nil:
+		Success(c, result)
+	case errors.Is(err, service.ErrSearchQueryRequired):
+		BadRequest(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrSearchIndexUnavailable):
+		Error(c, http.StatusServiceUnavailable, GetErrorMessage(err))
+	default:
+		InternalError(c, "搜索失败")
+	}
+}
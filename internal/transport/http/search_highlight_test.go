@@ -0,0 +1,77 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestSearchHighlightMarkers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "a@temp.mail", LocalPart: "a", Domain: "temp.mail", CreatedAt: time.Now(),
+	}))
+	messages := service.NewMessageService(store)
+	msg, err := messages.Create(t.Context(), service.CreateMessageInput{MailboxID: "mb-1", From: "noreply@example.com", Subject: "Your login code"})
+	require.NoError(t, err)
+
+	h := &Handler{messages: messages, search: service.NewSearchService(store)}
+	router := gin.New()
+	router.GET("/v1/mailboxes/:id/messages/search", h.searchMessages)
+
+	search := func(pre, post string) *httptest.ResponseRecorder {
+		query := url.Values{"q": {"code"}}
+		if pre != "" {
+			query.Set("highlightPre", pre)
+		}
+		if post != "" {
+			query.Set("highlightPost", post)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/mailboxes/mb-1/messages/search?"+query.Encode(), nil))
+		return w
+	}
+	subjectSnippet := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		t.Helper()
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Data domain.MessageSearchResult `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data.Snippets[msg.ID].Subject
+	}
+
+	t.Run("默认标记", func(t *testing.T) {
+		assert.Equal(t, "Your login <em>code</em>", subjectSnippet(t, search("", "")))
+	})
+
+	t.Run("白名单标签", func(t *testing.T) {
+		assert.Equal(t, "Your login <mark>code</mark>", subjectSnippet(t, search("<mark>", "</mark>")))
+	})
+
+	t.Run("只给出一侧时另一侧配对", func(t *testing.T) {
+		assert.Equal(t, "Your login <strong>code</strong>", subjectSnippet(t, search("<strong>", "")))
+		assert.Equal(t, "Your login <b>code</b>", subjectSnippet(t, search("", "</b>")))
+	})
+
+	t.Run("非白名单或带属性的标记返回 400", func(t *testing.T) {
+		w := search(`<img src=x onerror=alert(1)>`, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.NotContains(t, w.Body.String(), "onerror")
+		assert.Equal(t, http.StatusBadRequest, search(`<span onmouseover="alert(1)">`, "</span>").Code)
+		assert.Equal(t, http.StatusBadRequest, search("<mark>", "</em>").Code)
+	})
+}
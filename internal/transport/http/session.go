@@ -0,0 +1,143 @@
+package httptransport
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/auth"
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+)
+
+// sessionResponse 登录会话（不含令牌 jti）
+type sessionResponse struct {
+	ID         string    `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"` // 是否为发起请求的会话
+}
+
+// SetSessions 启用登录会话：登录时创建会话，刷新时轮换刷新令牌，支持注销
+func (h *AuthHandler) SetSessions(sessions *auth.SessionService) {
+	h.sessions = sessions
+}
+
+// SetSessions 第三方登录同样创建登录会话
+func (h *OAuthHandler) SetSessions(sessions *auth.SessionService) {
+	h.sessions = sessions
+}
+
+// issueTokens 登录成功后签发令牌；未启用会话时只签发无状态令牌（不能轮换和注销）
+func issueTokens(c *gin.Context, sessions *auth.SessionService, jwtManager *jwtpkg.Manager, user *domain.User) (*jwtpkg.TokenPair, error) {
+	if sessions == nil {
+		return jwtManager.GenerateTokenPair(c.Request.Context(), user.ID, user.Email, string(user.Tier))
+	}
+	return sessions.Start(c.Request.Context(), user, sessionClient(c))
+}
+
+// sessionClient 记录到会话中的客户端信息
+func sessionClient(c *gin.Context) auth.SessionClient {
+	return auth.SessionClient{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// tokenClaims 取出 RequireAuth 保存的访问令牌声明
+func tokenClaims(c *gin.Context) *jwtpkg.Claims {
+	value, ok := c.Get("claims")
+	if !ok {
+		return nil
+	}
+	claims, _ := value.(*jwtpkg.Claims)
+	return claims
+}
+
+// Logout godoc
+// @Summary 注销
+// @Description 注销当前会话：访问令牌立即失效（加入黑名单直到过期），该会话的刷新令牌不能再使用
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response
+// @Failure 401 {object} Response "未认证"
+// @Router /v1/auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims := tokenClaims(c)
+	if claims == nil {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+	if err := h.sessions.Logout(c.Request.Context(), claims); err != nil {
+		h.log.Error("failed to logout", zap.String("user_id", claims.UserID), zap.Error(err))
+		InternalError(c, MsgSessionFailed)
+		return
+	}
+	h.log.Info("user logged out", zap.String("user_id", claims.UserID), zap.String("session_id", claims.SessionID))
+	SuccessWithMsg(c, "已注销", nil)
+}
+
+// ListSessions godoc
+// @Summary 列出登录会话
+// @Description 列出当前用户未过期的登录会话（按最近使用时间倒序），current 标记发起请求的会话
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=[]sessionResponse}
+// @Failure 401 {object} Response "未认证"
+// @Router /v1/auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.sessions.List(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		h.log.Error("failed to list sessions", zap.Error(err))
+		InternalError(c, MsgSessionFailed)
+		return
+	}
+
+	var currentID string
+	if claims := tokenClaims(c); claims != nil {
+		currentID = claims.SessionID
+	}
+	result := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, sessionResponse{
+			ID:         session.ID,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentID,
+		})
+	}
+	Success(c, result)
+}
+
+// RevokeSession godoc
+// @Summary 撤销登录会话
+// @Description 注销指定会话（如丢失的设备）：该会话的刷新令牌失效，最近签发的访问令牌加入黑名单
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "会话ID"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response "未认证"
+// @Failure 404 {object} Response "会话不存在"
+// @Router /v1/auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetString("userID")
+	if err := h.sessions.Revoke(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			NotFound(c, MsgSessionNotFound)
+			return
+		}
+		h.log.Error("failed to revoke session", zap.String("user_id", userID), zap.Error(err))
+		InternalError(c, MsgSessionFailed)
+		return
+	}
+	h.log.Info("session revoked", zap.String("user_id", userID), zap.String("session_id", c.Param("id")))
+	SuccessWithMsg(c, "已撤销会话", nil)
+}
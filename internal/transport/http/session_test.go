@@ -0,0 +1,133 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/auth"
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestSessionHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(time.Hour)
+	authService := auth.NewService(store)
+	jwtManager := jwtpkg.NewManager("test-secret-0123456789abcdefghijklmnop", "test", time.Hour, 24*time.Hour)
+	sessions := auth.NewSessionService(store, store, jwtManager)
+	user, err := authService.Register(t.Context(), auth.RegisterInput{Email: "alice@example.com", Password: "password123", Username: "alice"})
+	require.NoError(t, err)
+
+	h := NewAuthHandler(authService, jwtManager)
+	h.SetSessions(sessions)
+	jwtAuth := middleware.NewJWTAuth(jwtManager)
+	jwtManager.SetBlacklist(sessions)
+
+	// 邮箱所有者可用访问令牌代替邮箱令牌，同样受黑名单约束
+	mailboxes := service.NewMailboxService(store, store, &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.mail"}, DefaultTTL: time.Hour}})
+	mailbox, err := mailboxes.Create(t.Context(), service.CreateMailboxInput{Prefix: "alice", Domain: "temp.mail", UserID: &user.ID})
+	require.NoError(t, err)
+	mailboxAuth := middleware.NewMailboxAuth(mailboxes)
+	mailboxAuth.SetUserAccess(jwtManager, service.NewAuthorizer(store))
+
+	router := gin.New()
+	router.POST("/v1/auth/login", h.Login)
+	router.POST("/v1/auth/refresh", h.Refresh)
+	router.POST("/v1/auth/logout", jwtAuth.RequireAuth(), h.Logout)
+	router.GET("/v1/auth/me", jwtAuth.RequireAuth(), h.Me)
+	router.GET("/v1/auth/sessions", jwtAuth.RequireAuth(), h.ListSessions)
+	router.DELETE("/v1/auth/sessions/:id", jwtAuth.RequireAuth(), h.RevokeSession)
+	router.GET("/v1/mailboxes/:id", mailboxAuth.RequireMailboxToken(), (&Handler{mailboxes: mailboxes}).getMailbox)
+
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(t *testing.T) authResponse {
+		w := serve(http.MethodPost, "/v1/auth/login", "", `{"username":"alice@example.com","password":"password123"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp authResponse
+		require.NoError(t, json.Unmarshal(mustData(t, w), &resp))
+		return resp
+	}
+	refresh := func(token string) *httptest.ResponseRecorder {
+		return serve(http.MethodPost, "/v1/auth/refresh", "", `{"refreshToken":"`+token+`"}`)
+	}
+
+	t.Run("注销后访问令牌和刷新令牌都失效", func(t *testing.T) {
+		tokens := login(t)
+		require.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/auth/me", tokens.AccessToken, "").Code)
+
+		require.Equal(t, http.StatusOK, serve(http.MethodPost, "/v1/auth/logout", tokens.AccessToken, "").Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/v1/auth/me", tokens.AccessToken, "").Code)
+		assert.Equal(t, http.StatusUnauthorized, refresh(tokens.RefreshToken).Code)
+	})
+
+	t.Run("注销后的访问令牌不能访问邮箱", func(t *testing.T) {
+		tokens := login(t)
+		require.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/mailboxes/"+mailbox.ID, tokens.AccessToken, "").Code)
+
+		require.Equal(t, http.StatusOK, serve(http.MethodPost, "/v1/auth/logout", tokens.AccessToken, "").Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/v1/mailboxes/"+mailbox.ID, tokens.AccessToken, "").Code)
+	})
+
+	t.Run("刷新轮换刷新令牌，重放旧令牌注销会话", func(t *testing.T) {
+		tokens := login(t)
+
+		w := refresh(tokens.RefreshToken)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var rotated jwtpkg.TokenPair
+		require.NoError(t, json.Unmarshal(mustData(t, w), &rotated))
+		require.NotEmpty(t, rotated.RefreshToken)
+		assert.NotEqual(t, tokens.RefreshToken, rotated.RefreshToken)
+
+		w = refresh(tokens.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), MsgRefreshTokenReused)
+		assert.Equal(t, http.StatusUnauthorized, refresh(rotated.RefreshToken).Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/v1/auth/me", rotated.AccessToken, "").Code)
+	})
+
+	t.Run("列出并撤销其他设备的会话", func(t *testing.T) {
+		laptop := login(t)
+		phone := login(t)
+
+		w := serve(http.MethodGet, "/v1/auth/sessions", laptop.AccessToken, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var list []sessionResponse
+		require.NoError(t, json.Unmarshal(mustData(t, w), &list))
+		require.Len(t, list, 2)
+
+		var phoneID string
+		for _, session := range list {
+			if !session.Current {
+				phoneID = session.ID
+			}
+		}
+		require.NotEmpty(t, phoneID)
+
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/v1/auth/sessions/unknown", laptop.AccessToken, "").Code)
+		require.Equal(t, http.StatusOK, serve(http.MethodDelete, "/v1/auth/sessions/"+phoneID, laptop.AccessToken, "").Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/v1/auth/me", phone.AccessToken, "").Code)
+		assert.Equal(t, http.StatusUnauthorized, refresh(phone.RefreshToken).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/auth/me", laptop.AccessToken, "").Code)
+	})
+}
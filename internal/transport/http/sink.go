@@ -0,0 +1,173 @@
+package httptransport
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+)
+
+// SinkHandler 域名黑洞模式API处理器
+type SinkHandler struct {
+	sinkService *service.SinkService
+}
+
+// NewSinkHandler 创建黑洞模式处理器
+func NewSinkHandler(sinkService *service.SinkService) *SinkHandler {
+	return &SinkHandler{
+		sinkService: sinkService,
+	}
+}
+
+// sinkSettingsRequest 黑洞模式设置请求
+type sinkSettingsRequest struct {
+	Enabled              bool   `json:"enabled"`
+	SampleRate           int    `json:"sampleRate"`           // 每 N 封抽样 1 封，0 表示不抽样
+	DiagnosticsMailboxID string `json:"diagnosticsMailboxId"` // 抽样邮件投递的邮箱
+}
+
+func (r sinkSettingsRequest) settings() domain.SinkSettings {
+	return domain.SinkSettings{
+		Enabled:              r.Enabled,
+		SampleRate:           r.SampleRate,
+		DiagnosticsMailboxID: r.DiagnosticsMailboxID,
+	}
+}
+
+// UpdateSystemDomainSink godoc
+// @Summary 设置系统域名黑洞模式
+// @Description 开启后域名下任意收件人都被接收，邮件不保存，只累计汇总统计；可按 1/N 抽样投递到诊断邮箱。关闭后立即恢复正常收件（需要超级管理员权限）
+// @Tags Admin - Domains
+// @Accept json
+// @Produce json
+// @Param id path string true "域名ID"
+// @Param request body sinkSettingsRequest true "黑洞模式设置"
+// @Success 200 {object} Response{data=domain.SystemDomain}
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/admin/domains/{id}/sink [put]
+func (h *SinkHandler) UpdateSystemDomainSink(c *gin.Context) {
+	var req sinkSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	sysDomain, err := h.sinkService.ConfigureSystemDomain(c.Request.Context(), c.Param("id"), req.settings())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSystemDomainNotFound):
+			NotFound(c, MsgDomainNotFoundAdmin)
+		case errors.Is(err, service.ErrInvalidSinkSampleRate), errors.Is(err, service.ErrSinkDiagnosticsMailbox):
+			BadRequest(c, GetErrorMessage(err))
+		default:
+			InternalError(c, MsgSinkUpdateFailed)
+		}
+		return
+	}
+
+	Success(c, sysDomain)
+}
+
+// GetSystemDomainSinkStats godoc
+// @Summary 获取系统域名黑洞统计
+// @Description 返回累计接收邮件数、字节数、近似去重发件人数、抽样数和保留期内的小时桶（需要管理员权限）
+// @Tags Admin - Domains
+// @Produce json
+// @Param id path string true "域名ID"
+// @Success 200 {object} Response{data=domain.SinkStats}
+// @Failure 404 {object} Response
+// @Router /v1/admin/domains/{id}/sink-stats [get]
+func (h *SinkHandler) GetSystemDomainSinkStats(c *gin.Context) {
+	stats, err := h.sinkService.SystemDomainStats(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrSystemDomainNotFound) {
+			NotFound(c, MsgDomainNotFoundAdmin)
+			return
+		}
+		InternalError(c, MsgSinkStatsGetFailed)
+		return
+	}
+
+	Success(c, stats)
+}
+
+// UpdateUserDomainSink godoc
+// @Summary 设置用户域名黑洞模式
+// @Description 仅已验证的独享模式域名可以开启；诊断邮箱必须是当前用户可管理的邮箱
+// @Tags User Domains
+// @Accept json
+// @Produce json
+// @Param id path string true "域名ID"
+// @Param request body sinkSettingsRequest true "黑洞模式设置"
+// @Success 200 {object} Response{data=domain.UserDomain}
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/user/domains/{id}/sink [put]
+func (h *SinkHandler) UpdateUserDomainSink(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	var req sinkSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	userDomain, err := h.sinkService.ConfigureUserDomain(c.Request.Context(), c.Param("id"), userID, req.settings())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDomainNotFound):
+			NotFound(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrNotDomainOwner):
+			Forbidden(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrSinkRequiresExclusive), errors.Is(err, service.ErrInvalidSinkSampleRate),
+			errors.Is(err, service.ErrSinkDiagnosticsMailbox):
+			BadRequest(c, GetErrorMessage(err))
+		default:
+			InternalError(c, MsgSinkUpdateFailed)
+		}
+		return
+	}
+
+	Success(c, userDomain)
+}
+
+// GetUserDomainSinkStats godoc
+// @Summary 获取用户域名黑洞统计
+// @Description 返回域名的黑洞模式汇总统计。仅域名所有者可访问
+// @Tags User Domains
+// @Produce json
+// @Param id path string true "域名ID"
+// @Success 200 {object} Response{data=domain.SinkStats}
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/user/domains/{id}/sink-stats [get]
+func (h *SinkHandler) GetUserDomainSinkStats(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	stats, err := h.sinkService.UserDomainStats(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDomainNotFound):
+			NotFound(c, GetErrorMessage(err))
+		case errors.Is(err, service.ErrNotDomainOwner):
+			Forbidden(c, GetErrorMessage(err))
+		default:
+			InternalError(c, MsgSinkStatsGetFailed)
+		}
+		return
+	}
+
+	Success(c, stats)
+}
@@ -0,0 +1,56 @@
+package httptransport
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/smtp"
+)
+
+// SMTPSessionHandler 活跃 SMTP 会话查看（排查卡住的投递）
+type SMTPSessionHandler struct {
+	sessions *smtp.SessionRegistry
+}
+
+// NewSMTPSessionHandler 创建 SMTP 会话处理器
+func NewSMTPSessionHandler(sessions *smtp.SessionRegistry) *SMTPSessionHandler {
+	return &SMTPSessionHandler{sessions: sessions}
+}
+
+// ListSessions godoc
+// @Summary 活跃 SMTP 会话
+// @Description 列出当前打开的 SMTP 会话：对端 IP、HELO、阶段（connected/mail/rcpt/data）、收件人数、已读取字节数、开始时间和最近活动时间。发件地址已遮盖
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=[]smtp.SessionInfo}
+// @Failure 403 {object} Response
+// @Router /v1/admin/smtp/sessions [get]
+func (h *SMTPSessionHandler) ListSessions(c *gin.Context) {
+	sessions := h.sessions.List()
+	Success(c, gin.H{
+		"items": sessions,
+		"count": len(sessions),
+	})
+}
+
+// CloseSession godoc
+// @Summary 强制关闭 SMTP 会话
+// @Description 断开卡住的 SMTP 会话连接，发件方会看到连接中断并稍后重试
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "会话ID"
+// @Success 204
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/admin/smtp/sessions/{id} [delete]
+func (h *SMTPSessionHandler) CloseSession(c *gin.Context) {
+	// 连接已在关闭中时断开连接会返回错误，会话仍已移除，按成功处理
+	if err := h.sessions.Close(c.Param("id")); errors.Is(err, smtp.ErrSessionNotFound) {
+		NotFound(c, MsgSMTPSessionNotFound)
+		return
+	}
+	NoContent(c)
+}
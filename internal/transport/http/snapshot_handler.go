@@ -0,0 +1,46 @@
+package httptransport
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StoreSnapshotter 可导出完整快照的存储（目前只有内存存储）
+type StoreSnapshotter interface {
+	WriteSnapshot(w io.Writer) error
+}
+
+// SnapshotHandler 存储快照导出（供 migrate-data 从运行中的实例迁移）
+type SnapshotHandler struct {
+	snapshotter StoreSnapshotter
+}
+
+// NewSnapshotHandler 创建存储快照处理器
+func NewSnapshotHandler(snapshotter StoreSnapshotter) *SnapshotHandler {
+	return &SnapshotHandler{snapshotter: snapshotter}
+}
+
+// ExportSnapshot godoc
+// @Summary 导出存储快照
+// @Description 以 gzip 压缩的 JSON 流式返回内存存储的全部内容（含密码哈希和 API Key），格式与 storage.snapshot_path 写入的快照文件相同。仅在使用内存存储时可用
+// @Tags Admin - Backup
+// @Produce application/gzip
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 403 {object} Response
+// @Router /v1/admin/backup/snapshot [get]
+func (h *SnapshotHandler) ExportSnapshot(c *gin.Context) {
+	filename := fmt.Sprintf("tempmail-snapshot-%s.json.gz", time.Now().UTC().Format("20060102T150405Z"))
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	// 已开始写响应，出错时只能中断连接
+	if err := h.snapshotter.WriteSnapshot(c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
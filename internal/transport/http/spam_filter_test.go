@@ -0,0 +1,81 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestSpamFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "qa@temp.mail", LocalPart: "qa", Domain: "temp.mail", CreatedAt: time.Now(),
+	}))
+	messages := service.NewMessageService(store)
+	for _, input := range []service.CreateMessageInput{
+		{Subject: "hello", SpamScore: 1},
+		{Subject: "buy now", SpamScore: 9, IsSpam: true, Quarantined: true},
+		{Subject: "invoice", SpamScore: 2, Quarantined: true}, // 病毒扫描隔离
+	} {
+		input.MailboxID, input.From = "mb-1", "sender@example.com"
+		_, err := messages.Create(t.Context(), input)
+		require.NoError(t, err)
+	}
+
+	h := &Handler{messages: messages, search: service.NewSearchService(store)}
+	router := gin.New()
+	router.GET("/v1/mailboxes/:id/messages", h.listMessages)
+	router.GET("/v1/mailboxes/:id/messages/search", h.searchMessages)
+
+	list := func(query string) []string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/mailboxes/mb-1/messages?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data messageListResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		subjects := make([]string, 0, resp.Data.Count)
+		for _, item := range resp.Data.Items {
+			subjects = append(subjects, item.Subject)
+		}
+		return subjects
+	}
+
+	t.Run("隔离区按垃圾邮件判定过滤", func(t *testing.T) {
+		assert.Equal(t, []string{"buy now"}, list("quarantined=true&isSpam=true"))
+		assert.Equal(t, []string{"invoice"}, list("quarantined=true&isSpam=false"))
+		assert.Empty(t, list("isSpam=true"))
+	})
+
+	t.Run("搜索按垃圾邮件判定过滤", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/mailboxes/mb-1/messages/search?isSpam=true", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data domain.MessageSearchResult `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, 1, resp.Data.Total)
+		assert.Equal(t, "buy now", resp.Data.Messages[0].Subject)
+		assert.True(t, resp.Data.Messages[0].IsSpam)
+	})
+
+	t.Run("无效的 isSpam 返回 400", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/mailboxes/mb-1/messages?isSpam=maybe", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
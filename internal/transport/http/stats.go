@@ -0,0 +1,79 @@
+package httptransport
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+)
+
+// mailboxStats godoc
+// @Summary 邮箱收件统计
+// @Description 返回窗口内按小时（UTC）的收件数、总数/未读数、前 10 位发件人和平均邮件大小。窗口支持 "7d" 或 "36h"，最长 90 天
+// @Tags Mailboxes
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param window query string false "统计窗口（默认 7d，最大 90d）"
+// @Success 200 {object} Response{data=domain.MessageStats}
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 500 {object} Response
+// @Router /v1/mailboxes/{id}/stats [get]
+func (h *Handler) mailboxStats(c *gin.Context) {
+	window, err := service.ParseStatsWindow(c.Query("window"))
+	if err != nil {
+		BadRequest(c, GetErrorMessage(err))
+		return
+	}
+
+	stats, err := h.stats.MailboxStats(c.Request.Context(), c.Param("id"), window)
+	if err != nil {
+		InternalError(c, MsgStatsGetFailed)
+		return
+	}
+
+	Success(c, stats)
+}
+
+// domainStats godoc
+// @Summary 用户域名收件统计
+// @Description 汇总域名下所有邮箱的收件统计，并按邮箱本地部分给出分布。仅域名所有者可访问
+// @Tags User Domains
+// @Produce json
+// @Param id path string true "域名ID"
+// @Param window query string false "统计窗口（默认 7d，最大 90d）"
+// @Success 200 {object} Response{data=domain.MessageStats}
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Router /v1/user/domains/{id}/stats [get]
+func (h *Handler) domainStats(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	window, err := service.ParseStatsWindow(c.Query("window"))
+	if err != nil {
+		BadRequest(c, GetErrorMessage(err))
+		return
+	}
+
+	stats, err := h.stats.DomainStats(c.Request.Context(), userID, c.Param("id"), window)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDomainNotFound):
+			NotFound(c, GetErrorMessage(service.ErrDomainNotFound))
+		case errors.Is(err, service.ErrNotDomainOwner):
+			Forbidden(c, GetErrorMessage(service.ErrNotDomainOwner))
+		default:
+			InternalError(c, MsgStatsGetFailed)
+		}
+		return
+	}
+
+	Success(c, stats)
+}
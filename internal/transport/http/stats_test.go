@@ -0,0 +1,74 @@
+package httptransport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestStatsRoutes_TokenScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	for _, mb := range []domain.Mailbox{
+		{ID: "mb-a", Address: "a@corp.example", LocalPart: "a", Domain: "corp.example", Token: "tok-a"},
+		{ID: "mb-b", Address: "b@other.example", LocalPart: "b", Domain: "other.example", Token: "tok-b"},
+	} {
+		mailbox := mb
+		mailbox.CreatedAt = time.Now()
+		require.NoError(t, store.SaveMailbox(t.Context(), &mailbox))
+	}
+	require.NoError(t, store.SaveUserDomain(t.Context(), &domain.UserDomain{ID: "ud-other", UserID: "user-2", Domain: "other.example"}))
+
+	mailboxes := service.NewMailboxService(store, store, &config.Config{})
+	jwtManager := jwtpkg.NewManager("test-secret", "test", time.Hour, 24*time.Hour)
+	mailboxAuth := middleware.NewMailboxAuth(mailboxes)
+	jwtAuth := middleware.NewJWTAuth(jwtManager)
+
+	h := &Handler{stats: service.NewStatsService(store)}
+	router := gin.New()
+	router.GET("/v1/mailboxes/:id/stats", mailboxAuth.RequireMailboxToken(), h.mailboxStats)
+	router.GET("/v1/user/domains/:id/stats", jwtAuth.RequireAuth(), h.domainStats)
+
+	get := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("邮箱Token可以读取自身统计", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get("/v1/mailboxes/mb-a/stats?window=7d", "tok-a"))
+	})
+
+	t.Run("邮箱Token不能读取其他邮箱统计", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, get("/v1/mailboxes/mb-b/stats", "tok-a"))
+	})
+
+	t.Run("邮箱Token不能读取域名统计", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, get("/v1/user/domains/ud-other/stats", "tok-a"))
+	})
+
+	t.Run("非所有者的JWT被拒绝", func(t *testing.T) {
+		pair, err := jwtManager.GenerateTokenPair(t.Context(), "user-1", "u1@example.com", "free")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, get("/v1/user/domains/ud-other/stats", pair.AccessToken))
+	})
+
+	t.Run("窗口超过90天被拒绝", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("/v1/mailboxes/mb-a/stats?window=120d", "tok-a"))
+	})
+}
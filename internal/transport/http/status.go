@@ -0,0 +1,41 @@
+package httptransport
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/monitoring"
+)
+
+// StatusHandler 公开状态处理器（无需认证）
+type StatusHandler struct {
+	monitor *monitoring.StatusMonitor
+}
+
+// NewStatusHandler 创建公开状态处理器
+func NewStatusHandler(monitor *monitoring.StatusMonitor) *StatusHandler {
+	return &StatusHandler{monitor: monitor}
+}
+
+// GetStatus godoc
+// @Summary 获取服务状态
+// @Description 获取各组件的粗粒度状态（ok/degraded/down），用于状态页展示（公开接口，无需认证）
+// @Tags Public
+// @Produce json
+// @Success 200 {object} Response{data=monitoring.StatusSnapshot}
+// @Failure 429 {object} Response
+// @Router /v1/public/status [get]
+func (h *StatusHandler) GetStatus(c *gin.Context) {
+	Success(c, h.monitor.Current())
+}
+
+// GetStatusHistory godoc
+// @Summary 获取服务可用性历史
+// @Description 获取最近 24 小时（按小时）和 7 天（按天）各组件的可用率（公开接口，无需认证）
+// @Tags Public
+// @Produce json
+// @Success 200 {object} Response{data=monitoring.UptimeHistory}
+// @Failure 429 {object} Response
+// @Router /v1/public/status/history [get]
+func (h *StatusHandler) GetStatusHistory(c *gin.Context) {
+	Success(c, h.monitor.History())
+}
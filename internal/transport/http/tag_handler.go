@@ -42,8 +42,11 @@ func (h *Handler) createTag(c *gin.Context) {
 	}
 	input.UserID = userID.(string)
 
-	tag, err := h.tag.CreateTag(input)
+	tag, err := h.tag.CreateTag(c.Request.Context(), input)
 	if err != nil {
+		if respondOrgError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, errorResponse{
 			Error: err.Error(),
 		})
@@ -73,7 +76,7 @@ func (h *Handler) listTags(c *gin.Context) {
 		return
 	}
 
-	tags, err := h.tag.ListTags(userID.(string))
+	tags, err := h.tag.ListTags(c.Request.Context(), userID.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse{
 			Error: "获取标签列表失败",
@@ -99,7 +102,7 @@ func (h *Handler) listTags(c *gin.Context) {
 func (h *Handler) getTag(c *gin.Context) {
 	id := c.Param("id")
 
-	tag, err := h.tag.GetTag(id)
+	tag, err := h.tag.GetTag(c.Request.Context(), id)
 	if err != nil {
 		NotFound(c, "标签不存在")
 		return
@@ -107,7 +110,7 @@ func (h *Handler) getTag(c *gin.Context) {
 
 	// 验证权限
 	userID, _ := c.Get("userID")
-	if tag.UserID != userID.(string) {
+	if !h.authz.Can(c.Request.Context(), userID.(string), tag.UserID, tag.OrgID, service.ActionRead) {
 		Forbidden(c, "无权访问")
 		return
 	}
@@ -133,14 +136,14 @@ func (h *Handler) updateTag(c *gin.Context) {
 	id := c.Param("id")
 
 	// 验证权限
-	tag, err := h.tag.GetTag(id)
+	tag, err := h.tag.GetTag(c.Request.Context(), id)
 	if err != nil {
 		NotFound(c, "标签不存在")
 		return
 	}
 
 	userID, _ := c.Get("userID")
-	if tag.UserID != userID.(string) {
+	if !h.authz.Can(c.Request.Context(), userID.(string), tag.UserID, tag.OrgID, service.ActionWrite) {
 		Forbidden(c, "无权访问")
 		return
 	}
@@ -153,7 +156,7 @@ func (h *Handler) updateTag(c *gin.Context) {
 		return
 	}
 
-	updated, err := h.tag.UpdateTag(id, input)
+	updated, err := h.tag.UpdateTag(c.Request.Context(), id, input)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, errorResponse{
 			Error: err.Error(),
@@ -180,19 +183,19 @@ func (h *Handler) deleteTag(c *gin.Context) {
 	id := c.Param("id")
 
 	// 验证权限
-	tag, err := h.tag.GetTag(id)
+	tag, err := h.tag.GetTag(c.Request.Context(), id)
 	if err != nil {
 		NotFound(c, "标签不存在")
 		return
 	}
 
 	userID, _ := c.Get("userID")
-	if tag.UserID != userID.(string) {
+	if !h.authz.Can(c.Request.Context(), userID.(string), tag.UserID, tag.OrgID, service.ActionWrite) {
 		Forbidden(c, "无权访问")
 		return
 	}
 
-	if err := h.tag.DeleteTag(id); err != nil {
+	if err := h.tag.DeleteTag(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse{
 			Error: "删除标签失败",
 		})
@@ -230,19 +233,19 @@ func (h *Handler) addMessageTag(c *gin.Context) {
 	}
 
 	// 验证标签权限
-	tag, err := h.tag.GetTag(input.TagID)
+	tag, err := h.tag.GetTag(c.Request.Context(), input.TagID)
 	if err != nil {
 		NotFound(c, "标签不存在")
 		return
 	}
 
 	userID, _ := c.Get("userID")
-	if tag.UserID != userID.(string) {
+	if !h.authz.Can(c.Request.Context(), userID.(string), tag.UserID, tag.OrgID, service.ActionWrite) {
 		Forbidden(c, "无权访问该标签")
 		return
 	}
 
-	if err := h.tag.AddMessageTag(messageID, input.TagID); err != nil {
+	if err := h.tag.AddMessageTag(c.Request.Context(), messageID, input.TagID); err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse{
 			Error: "添加标签失败",
 		})
@@ -271,19 +274,19 @@ func (h *Handler) removeMessageTag(c *gin.Context) {
 	tagID := c.Param("tagId")
 
 	// 验证标签权限
-	tag, err := h.tag.GetTag(tagID)
+	tag, err := h.tag.GetTag(c.Request.Context(), tagID)
 	if err != nil {
 		NotFound(c, "标签不存在")
 		return
 	}
 
 	userID, _ := c.Get("userID")
-	if tag.UserID != userID.(string) {
+	if !h.authz.Can(c.Request.Context(), userID.(string), tag.UserID, tag.OrgID, service.ActionWrite) {
 		Forbidden(c, "无权访问该标签")
 		return
 	}
 
-	if err := h.tag.RemoveMessageTag(messageID, tagID); err != nil {
+	if err := h.tag.RemoveMessageTag(c.Request.Context(), messageID, tagID); err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse{
 			Error: "移除标签失败",
 		})
@@ -309,7 +312,7 @@ func (h *Handler) removeMessageTag(c *gin.Context) {
 func (h *Handler) getMessageTags(c *gin.Context) {
 	messageID := c.Param("messageId")
 
-	tags, err := h.tag.GetMessageTags(messageID)
+	tags, err := h.tag.GetMessageTags(c.Request.Context(), messageID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse{
 			Error: "获取标签失败",
@@ -336,19 +339,19 @@ func (h *Handler) listMessagesByTag(c *gin.Context) {
 	tagID := c.Param("id")
 
 	// 验证标签权限
-	tag, err := h.tag.GetTag(tagID)
+	tag, err := h.tag.GetTag(c.Request.Context(), tagID)
 	if err != nil {
 		NotFound(c, "标签不存在")
 		return
 	}
 
 	userID, _ := c.Get("userID")
-	if tag.UserID != userID.(string) {
+	if !h.authz.Can(c.Request.Context(), userID.(string), tag.UserID, tag.OrgID, service.ActionRead) {
 		Forbidden(c, "无权访问")
 		return
 	}
 
-	messages, err := h.tag.ListMessagesByTag(tagID)
+	messages, err := h.tag.ListMessagesByTag(c.Request.Context(), tagID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse{
 			Error: "获取邮件列表失败",
@@ -0,0 +1,159 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/auth"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+type maintenanceOn struct{}
+
+func (maintenanceOn) MaintenanceState() (bool, string) { return true, "" }
+
+// assertThrottled 校验 Retry-After 头与响应体中的 errorCode、retryAfterSeconds 一致，返回 data
+func assertThrottled(t *testing.T, w *httptest.ResponseRecorder, status int, errorCode string) map[string]interface{} {
+	t.Helper()
+	require.Equal(t, status, w.Code, w.Body.String())
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.Positive(t, retryAfter)
+
+	var resp struct {
+		Code int                    `json:"code"`
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, status, resp.Code)
+	assert.Equal(t, errorCode, resp.Data["errorCode"])
+	assert.Equal(t, float64(retryAfter), resp.Data["retryAfterSeconds"])
+	return resp.Data
+}
+
+func TestThrottledResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	t.Run("IP 限流", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/v1/public", middleware.IPRateLimit(1, time.Minute), ok)
+
+		w := serve(router, http.MethodGet, "/v1/public", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+		w = serve(router, http.MethodGet, "/v1/public", "")
+		assertThrottled(t, w, http.StatusTooManyRequests, middleware.ErrorCodeRateLimited)
+		assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+		assert.NotEmpty(t, w.Header().Get("X-RateLimit-Reset"))
+	})
+
+	t.Run("维护模式", func(t *testing.T) {
+		router := gin.New()
+		router.Use(middleware.ReadOnlyMode(maintenanceOn{}))
+		router.POST("/v1/mailboxes", ok)
+		router.POST("/api/emails/generate", ok)
+
+		data := assertThrottled(t, serve(router, http.MethodPost, "/v1/mailboxes", "{}"), http.StatusServiceUnavailable, middleware.ErrorCodeMaintenance)
+		assert.Equal(t, true, data["maintenance"])
+		assert.Equal(t, strconv.Itoa(middleware.MaintenanceRetryAfter), serve(router, http.MethodPost, "/v1/mailboxes", "{}").Header().Get("Retry-After"))
+
+		// 兼容 API 沿用上游的 {"error": ...} 格式，重试提示放在顶层
+		w := serve(router, http.MethodPost, "/api/emails/generate", "{}")
+		require.Equal(t, http.StatusServiceUnavailable, w.Code)
+		var compat struct {
+			Error             string `json:"error"`
+			ErrorCode         string `json:"errorCode"`
+			RetryAfterSeconds int    `json:"retryAfterSeconds"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &compat))
+		assert.NotEmpty(t, compat.Error)
+		assert.Equal(t, middleware.ErrorCodeMaintenance, compat.ErrorCode)
+		assert.Equal(t, w.Header().Get("Retry-After"), strconv.Itoa(compat.RetryAfterSeconds))
+	})
+
+	t.Run("登录锁定与 IP 封禁", func(t *testing.T) {
+		store := memory.NewStore(time.Hour)
+		hash, err := auth.HashPassword("password123")
+		require.NoError(t, err)
+		until := time.Now().Add(10 * time.Minute)
+		require.NoError(t, store.CreateUser(t.Context(), &domain.User{
+			ID: "user-1", Email: "locked@example.com", Username: "locked", PasswordHash: hash, IsActive: true, LockedUntil: &until,
+		}))
+		authService := auth.NewService(store)
+		authService.SetLoginGuard(auth.NewLoginGuard(store))
+
+		router := gin.New()
+		router.POST("/v1/auth/login", NewAuthHandler(authService, nil).Login)
+
+		w := serve(router, http.MethodPost, "/v1/auth/login", `{"username":"locked@example.com","password":"password123"}`)
+		data := assertThrottled(t, w, http.StatusLocked, middleware.ErrorCodeTryLater)
+		assert.Equal(t, ReasonAccountLocked, data["reason"])
+		assert.InDelta(t, 600, data["retryAfterSeconds"], 2)
+
+		for range auth.LoginIPBlockAfter {
+			serve(router, http.MethodPost, "/v1/auth/login", `{"username":"nobody","password":"password123"}`)
+		}
+		w = serve(router, http.MethodPost, "/v1/auth/login", `{"username":"nobody","password":"password123"}`)
+		data = assertThrottled(t, w, http.StatusTooManyRequests, middleware.ErrorCodeRateLimited)
+		assert.Equal(t, ReasonTooManyAttempts, data["reason"])
+		assert.Equal(t, strconv.Itoa(int(auth.LoginIPBlockDuration.Seconds())), w.Header().Get("Retry-After"))
+	})
+
+	t.Run("数据导出配额", func(t *testing.T) {
+		store := memory.NewStore(time.Hour)
+		require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: "user-1", Email: "u@example.com", IsActive: true}))
+		handler := NewUserDataHandler(service.NewUserDataService(store), nil)
+
+		router := gin.New()
+		router.GET("/v1/auth/me/data-export", func(c *gin.Context) { c.Set("userID", "user-1") }, handler.ExportMyData)
+
+		require.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/v1/auth/me/data-export", "").Code)
+		w := serve(router, http.MethodGet, "/v1/auth/me/data-export", "")
+		assertThrottled(t, w, http.StatusTooManyRequests, middleware.ErrorCodeQuotaExceeded)
+		assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	})
+
+	t.Run("组织配额", func(t *testing.T) {
+		router := gin.New()
+		router.POST("/v1/orgs/:id/mailboxes", func(c *gin.Context) { respondOrgError(c, service.ErrOrgQuotaExceeded) })
+
+		w := serve(router, http.MethodPost, "/v1/orgs/org-1/mailboxes", "")
+		assertThrottled(t, w, http.StatusTooManyRequests, middleware.ErrorCodeQuotaExceeded)
+		assert.Equal(t, strconv.Itoa(int(orgQuotaRetryAfter.Seconds())), w.Header().Get("Retry-After"))
+	})
+
+	t.Run("开发投递遇到 SMTP 临时错误", func(t *testing.T) {
+		router := gin.New()
+		router.POST("/v1/dev/send", func(c *gin.Context) {
+			(&DevHandler{}).injectError(c, &gosmtp.SMTPError{Code: 451, Message: "try again later"})
+		})
+
+		w := serve(router, http.MethodPost, "/v1/dev/send", "")
+		assertThrottled(t, w, http.StatusServiceUnavailable, middleware.ErrorCodeTryLater)
+	})
+}
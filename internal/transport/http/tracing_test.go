@@ -0,0 +1,88 @@
+package httptransport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"tempmail/backend/internal/middleware"
+)
+
+func TestTracing_ServerSpans(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	spans := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	// 与 NewRouter 相同的顺序：链路追踪在 panic 恢复之外
+	router := gin.New()
+	router.Use(middleware.Tracing(), middleware.RecoveryHandler())
+	var handlerSpan trace.SpanContext
+	router.GET("/v1/mailboxes/:id", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+	router.GET("/v1/broken", func(c *gin.Context) { panic("boom") })
+
+	serve := func(path string, header http.Header) sdktrace.ReadOnlySpan {
+		t.Helper()
+		before := len(spans.Ended())
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		ended := spans.Ended()
+		require.Len(t, ended, before+1)
+		return ended[before]
+	}
+
+	t.Run("span 名使用路由模板，沿用请求头中的 traceparent", func(t *testing.T) {
+		const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		span := serve("/v1/mailboxes/mb-123", http.Header{"Traceparent": {traceparent}})
+
+		assert.Equal(t, "GET /v1/mailboxes/:id", span.Name())
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		assert.Contains(t, span.Attributes(), attribute.String("http.route", "/v1/mailboxes/:id"))
+		assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+		assert.Equal(t, codes.Unset, span.Status().Code)
+
+		assert.True(t, span.Parent().IsRemote())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+		assert.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID(), "处理器的请求上下文带有该 span")
+	})
+
+	t.Run("panic 恢复后的 500 标记为错误", func(t *testing.T) {
+		span := serve("/v1/broken", nil)
+
+		assert.Equal(t, "GET /v1/broken", span.Name())
+		assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusInternalServerError))
+		assert.Equal(t, codes.Error, span.Status().Code)
+		assert.False(t, span.Parent().IsValid(), "没有 traceparent 时为根 span")
+	})
+
+	t.Run("未匹配的路由不以原始路径命名", func(t *testing.T) {
+		span := serve("/v1/unknown/abc", nil)
+
+		assert.Equal(t, "GET", span.Name())
+		assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusNotFound))
+		assert.Equal(t, codes.Unset, span.Status().Code)
+	})
+}
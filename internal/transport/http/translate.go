@@ -0,0 +1,66 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+	"tempmail/backend/internal/translate"
+)
+
+// ReasonTranslationNotConfigured 未配置翻译服务时返回的原因代码
+const ReasonTranslationNotConfigured = "translation_not_configured"
+
+type translateMessageRequest struct {
+	To string `json:"to" binding:"required"` // 目标语言（ISO 639-1）
+}
+
+// translateMessage godoc
+// @Summary 翻译邮件
+// @Description 将邮件正文翻译为目标语言（HTML 邮件翻译提取出的纯文本），结果按语言缓存
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param messageId path string true "邮件ID"
+// @Param request body translateMessageRequest true "目标语言"
+// @Success 200 {object} Response{data=service.TranslationResult}
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 501 {object} Response
+// @Failure 502 {object} Response
+// @Router /v1/mailboxes/{id}/messages/{messageId}/translate [post]
+func (h *Handler) translateMessage(c *gin.Context) {
+	var req translateMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	result, err := h.messages.Translate(c.Request.Context(), c.Param("id"), c.Param("messageId"), req.To)
+	if err != nil {
+		var providerErr *translate.ProviderError
+		switch {
+		case errors.Is(err, service.ErrTranslationNotConfigured):
+			c.JSON(http.StatusNotImplemented, Response{
+				Code: CodeNotImplemented,
+				Msg:  GetErrorMessage(err),
+				Data: gin.H{"reason": ReasonTranslationNotConfigured},
+			})
+		case errors.Is(err, service.ErrInvalidTargetLanguage), errors.Is(err, service.ErrNothingToTranslate):
+			BadRequest(c, GetErrorMessage(err))
+		case errors.Is(err, memory.ErrMessageNotFound):
+			NotFound(c, MsgMessageNotFound)
+		case errors.As(err, &providerErr):
+			Error(c, http.StatusBadGateway, MsgMessageTranslateFailed+": "+providerErr.Error())
+		default:
+			InternalError(c, MsgMessageTranslateFailed)
+		}
+		return
+	}
+
+	Success(c, result)
+}
@@ -0,0 +1,49 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+func TestTranslateMessage_NotConfigured(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(24 * time.Hour)
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{
+		ID: "mb-1", Address: "a@temp.mail", LocalPart: "a", Domain: "temp.mail", CreatedAt: time.Now(),
+	}))
+	messages := service.NewMessageService(store)
+	msg, err := messages.Create(t.Context(), service.CreateMessageInput{MailboxID: "mb-1", Subject: "Hallo", Text: "Bitte bestätigen Sie Ihre Adresse."})
+	require.NoError(t, err)
+
+	h := &Handler{messages: messages}
+	router := gin.New()
+	router.POST("/v1/mailboxes/:id/messages/:messageId/translate", h.translateMessage)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/mailboxes/mb-1/messages/"+msg.ID+"/translate", strings.NewReader(`{"to":"en"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	var resp struct {
+		Code int               `json:"code"`
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, CodeNotImplemented, resp.Code)
+	assert.Equal(t, ReasonTranslationNotConfigured, resp.Data["reason"])
+}
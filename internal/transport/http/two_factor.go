@@ -0,0 +1,264 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/auth"
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/middleware"
+)
+
+// twoFactorChallengeTTL 密码验证通过后输入两步验证码的时限
+const twoFactorChallengeTTL = 5 * time.Minute
+
+// twoFactorChallengeResponse 已启用两步验证的账户密码正确后的响应（不含令牌）
+type twoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+	ExpiresIn         int64  `json:"expiresIn"` // 秒
+}
+
+// twoFactorSetupResponse 待确认的 TOTP 密钥
+type twoFactorSetupResponse struct {
+	Secret     string `json:"secret"`     // Base32 密钥（手动输入）
+	OTPAuthURL string `json:"otpauthUrl"` // 生成二维码用
+}
+
+// twoFactorRecoveryResponse 启用成功后返回的恢复码（只返回这一次）
+type twoFactorRecoveryResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"` // 6 位验证码或恢复码
+}
+
+type twoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// newTwoFactorChallenge 签发两步验证质询令牌
+func newTwoFactorChallenge(jwtManager *jwtpkg.Manager, userID string) (*twoFactorChallengeResponse, error) {
+	token, err := jwtManager.GenerateChallengeToken(userID, jwtpkg.PurposeTwoFactor, twoFactorChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int64(twoFactorChallengeTTL.Seconds()),
+	}, nil
+}
+
+// respondTwoFactorChallenge 返回两步验证质询
+func (h *AuthHandler) respondTwoFactorChallenge(c *gin.Context, userID string) {
+	challenge, err := newTwoFactorChallenge(h.jwtManager, userID)
+	if err != nil {
+		h.log.Error("failed to generate two-factor challenge", zap.Error(err))
+		InternalError(c, "生成令牌失败")
+		return
+	}
+	Success(c, challenge)
+}
+
+// respondLoginThrottled 账户锁定或 IP 被封禁时返回 423/429，已处理时返回 true
+func respondLoginThrottled(c *gin.Context, err error) bool {
+	var locked *auth.LockedError
+	switch {
+	case errors.As(err, &locked):
+		middleware.RespondThrottled(c, middleware.Throttle{
+			Status:     http.StatusLocked,
+			ErrorCode:  middleware.ErrorCodeTryLater,
+			Message:    MsgAccountLocked,
+			RetryAfter: time.Until(locked.Until),
+			Data:       gin.H{"reason": ReasonAccountLocked, "lockedUntil": locked.Until},
+		})
+	case errors.Is(err, auth.ErrTooManyAttempts):
+		middleware.RespondThrottled(c, middleware.Throttle{
+			Status:     http.StatusTooManyRequests,
+			ErrorCode:  middleware.ErrorCodeRateLimited,
+			Message:    MsgTooManyLoginAttempts,
+			RetryAfter: auth.LoginIPBlockDuration,
+			Data:       gin.H{"reason": ReasonTooManyAttempts},
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+// respondTwoFactorError 两步验证设置接口的通用错误映射
+func (h *AuthHandler) respondTwoFactorError(c *gin.Context, err error) {
+	if respondLoginThrottled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		NotFound(c, MsgUserNotFound)
+	case errors.Is(err, auth.ErrTwoFactorUnavailable):
+		Error(c, http.StatusServiceUnavailable, MsgTwoFactorUnavailable)
+	case errors.Is(err, auth.ErrTwoFactorEnabled):
+		Conflict(c, MsgTwoFactorEnabled)
+	case errors.Is(err, auth.ErrTwoFactorNotEnabled):
+		BadRequest(c, MsgTwoFactorNotEnabled)
+	case errors.Is(err, auth.ErrTwoFactorNotSetup):
+		BadRequest(c, MsgTwoFactorNotSetup)
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		BadRequest(c, MsgTwoFactorCodeInvalid)
+	default:
+		h.log.Error("two-factor operation failed", zap.Error(err))
+		InternalError(c, MsgTwoFactorFailed)
+	}
+}
+
+// SetupTwoFactor godoc
+// @Summary 生成两步验证密钥
+// @Description 生成新的 TOTP 密钥（SHA1、6 位、30 秒），用认证器应用扫描 otpauthUrl 后调用 /v1/auth/2fa/verify 启用；确认前可重复生成
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=twoFactorSetupResponse}
+// @Failure 401 {object} Response "未认证"
+// @Failure 409 {object} Response "已启用两步验证"
+// @Router /v1/auth/2fa/setup [post]
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	setup, err := h.authService.SetupTwoFactor(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		h.respondTwoFactorError(c, err)
+		return
+	}
+	Success(c, twoFactorSetupResponse{Secret: setup.Secret, OTPAuthURL: setup.URL})
+}
+
+// VerifyTwoFactor godoc
+// @Summary 启用两步验证
+// @Description 提交认证器生成的验证码确认密钥，启用后返回 10 个一次性恢复码（只返回这一次，手机丢失时代替验证码使用）
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body twoFactorCodeRequest true "验证码"
+// @Success 200 {object} Response{data=twoFactorRecoveryResponse}
+// @Failure 400 {object} Response "验证码无效或尚未生成密钥"
+// @Failure 409 {object} Response "已启用两步验证"
+// @Router /v1/auth/2fa/verify [post]
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	codes, err := h.authService.VerifyTwoFactorSetup(c.Request.Context(), c.GetString("userID"), req.Code)
+	if err != nil {
+		h.respondTwoFactorError(c, err)
+		return
+	}
+	h.log.Info("two-factor authentication enabled", zap.String("user_id", c.GetString("userID")))
+	Success(c, twoFactorRecoveryResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor godoc
+// @Summary 关闭两步验证
+// @Description 需要当前验证码或一个恢复码；错误计入登录失败次数
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body twoFactorCodeRequest true "验证码或恢复码"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response "验证码无效或未启用两步验证"
+// @Failure 423 {object} Response "账户已临时锁定"
+// @Router /v1/auth/2fa/disable [post]
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	if err := h.authService.DisableTwoFactor(c.Request.Context(), c.GetString("userID"), req.Code, c.ClientIP()); err != nil {
+		h.respondTwoFactorError(c, err)
+		return
+	}
+	h.log.Info("two-factor authentication disabled", zap.String("user_id", c.GetString("userID")))
+	SuccessWithMsg(c, "已关闭两步验证", nil)
+}
+
+// LoginTwoFactor godoc
+// @Summary 两步验证登录
+// @Description 登录第二步：提交 /v1/auth/login 返回的质询令牌（5 分钟有效）和验证码或恢复码，成功后返回认证令牌
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body twoFactorLoginRequest true "质询令牌和验证码"
+// @Success 200 {object} authResponse "登录成功"
+// @Failure 401 {object} Response "质询令牌过期或验证码无效"
+// @Failure 403 {object} Response "账户已被禁用"
+// @Failure 423 {object} Response "连续失败，账户已临时锁定"
+// @Failure 429 {object} Response "该 IP 登录失败次数过多"
+// @Router /v1/auth/2fa/login [post]
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req twoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+	userID, err := h.jwtManager.ValidateChallengeToken(req.ChallengeToken, jwtpkg.PurposeTwoFactor)
+	if err != nil {
+		Unauthorized(c, MsgTwoFactorChallengeInvalid)
+		return
+	}
+
+	user, err := h.authService.VerifyTwoFactorLogin(c.Request.Context(), auth.TwoFactorLoginInput{
+		UserID:    userID,
+		Code:      req.Code,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		if respondLoginThrottled(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+			Unauthorized(c, MsgTwoFactorCodeInvalid)
+		case errors.Is(err, auth.ErrUserInactive):
+			Forbidden(c, "账户已被禁用")
+		case errors.Is(err, auth.ErrUserNotFound), errors.Is(err, auth.ErrTwoFactorNotEnabled):
+			Unauthorized(c, MsgTwoFactorChallengeInvalid)
+		default:
+			h.log.Error("failed to verify two-factor login", zap.Error(err))
+			InternalError(c, "登录失败，请稍后重试")
+		}
+		return
+	}
+
+	tokens, err := issueTokens(c, h.sessions, h.jwtManager, user)
+	if err != nil {
+		h.log.Error("failed to generate tokens", zap.Error(err))
+		InternalError(c, "生成令牌失败")
+		return
+	}
+
+	h.log.Info("user logged in with two-factor authentication", zap.String("user_id", user.ID))
+
+	Success(c, authResponse{
+		User: userResponse{
+			ID:               user.ID,
+			Email:            user.Email,
+			Username:         user.Username,
+			Tier:             string(user.Tier),
+			IsActive:         user.IsActive,
+			IsEmailVerified:  user.IsEmailVerified,
+			TwoFactorEnabled: user.TOTPEnabled,
+		},
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	})
+}
@@ -0,0 +1,125 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/auth"
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// staticTwoFactorPolicy 固定的管理员两步验证要求
+type staticTwoFactorPolicy bool
+
+func (p staticTwoFactorPolicy) RequireAdminTwoFactor() bool { return bool(p) }
+
+func TestTwoFactorHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(time.Hour)
+	authService := auth.NewService(store)
+	cipher, err := auth.NewSecretCipher("test-two-factor-key")
+	require.NoError(t, err)
+	authService.SetTwoFactor(cipher, "TempMail")
+	jwtManager := jwtpkg.NewManager("test-secret-0123456789abcdefghijklmnop", "test", time.Hour, 24*time.Hour)
+
+	user, err := authService.Register(t.Context(), auth.RegisterInput{Email: "alice@example.com", Password: "password123", Username: "alice"})
+	require.NoError(t, err)
+	user.Role = domain.RoleAdmin
+	require.NoError(t, store.UpdateUser(t.Context(), user))
+
+	h := NewAuthHandler(authService, jwtManager)
+	jwtAuth := middleware.NewJWTAuth(jwtManager)
+	adminAuth := middleware.NewAdminAuth(authService)
+	adminAuth.SetTwoFactorPolicy(staticTwoFactorPolicy(true))
+
+	router := gin.New()
+	router.POST("/v1/auth/login", h.Login)
+	router.POST("/v1/auth/2fa/setup", jwtAuth.RequireAuth(), h.SetupTwoFactor)
+	router.POST("/v1/auth/2fa/verify", jwtAuth.RequireAuth(), h.VerifyTwoFactor)
+	router.POST("/v1/auth/2fa/login", h.LoginTwoFactor)
+	router.GET("/v1/admin/users", jwtAuth.RequireAuth(), adminAuth.RequireAdmin(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(t *testing.T) json.RawMessage {
+		w := serve(http.MethodPost, "/v1/auth/login", "", `{"username":"alice@example.com","password":"password123"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return mustData(t, w)
+	}
+
+	var first authResponse
+	require.NoError(t, json.Unmarshal(login(t), &first))
+	require.NotEmpty(t, first.AccessToken)
+
+	t.Run("系统要求两步验证时未启用的管理员被拒绝", func(t *testing.T) {
+		w := serve(http.MethodGet, "/v1/admin/users", first.AccessToken, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), middleware.ErrorCodeTwoFactorRequired)
+	})
+
+	var setup twoFactorSetupResponse
+	w := serve(http.MethodPost, "/v1/auth/2fa/setup", first.AccessToken, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(mustData(t, w), &setup))
+	assert.True(t, strings.HasPrefix(setup.OTPAuthURL, "otpauth://totp/TempMail:alice@example.com?"))
+
+	code, err := auth.TOTPCode(setup.Secret, time.Now())
+	require.NoError(t, err)
+	w = serve(http.MethodPost, "/v1/auth/2fa/verify", first.AccessToken, `{"code":"`+code+`"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var recovery twoFactorRecoveryResponse
+	require.NoError(t, json.Unmarshal(mustData(t, w), &recovery))
+	require.Len(t, recovery.RecoveryCodes, auth.RecoveryCodeCount)
+
+	t.Run("启用后登录只返回质询令牌", func(t *testing.T) {
+		var challenge twoFactorChallengeResponse
+		require.NoError(t, json.Unmarshal(login(t), &challenge))
+		assert.True(t, challenge.TwoFactorRequired)
+		assert.NotEmpty(t, challenge.ChallengeToken)
+
+		w := serve(http.MethodGet, "/v1/admin/users", challenge.ChallengeToken, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "质询令牌不能当作访问令牌")
+	})
+
+	t.Run("验证码错误时拒绝", func(t *testing.T) {
+		var challenge twoFactorChallengeResponse
+		require.NoError(t, json.Unmarshal(login(t), &challenge))
+		w := serve(http.MethodPost, "/v1/auth/2fa/login", "", `{"challengeToken":"`+challenge.ChallengeToken+`","code":"abcde-fghij"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = serve(http.MethodPost, "/v1/auth/2fa/login", "", `{"challengeToken":"forged","code":"`+recovery.RecoveryCodes[0]+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("恢复码完成登录后可访问管理接口", func(t *testing.T) {
+		var challenge twoFactorChallengeResponse
+		require.NoError(t, json.Unmarshal(login(t), &challenge))
+		w := serve(http.MethodPost, "/v1/auth/2fa/login", "", `{"challengeToken":"`+challenge.ChallengeToken+`","code":"`+recovery.RecoveryCodes[0]+`"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp authResponse
+		require.NoError(t, json.Unmarshal(mustData(t, w), &resp))
+		assert.True(t, resp.User.TwoFactorEnabled)
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/admin/users", resp.AccessToken, "").Code)
+	})
+}
@@ -0,0 +1,94 @@
+package httptransport
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/middleware"
+	"tempmail/backend/internal/service"
+)
+
+// UserDataHandler 个人数据导出与数据保留报告处理器
+type UserDataHandler struct {
+	userData  *service.UserDataService
+	retention *service.RetentionService
+}
+
+// NewUserDataHandler 创建个人数据处理器
+func NewUserDataHandler(userData *service.UserDataService, retention *service.RetentionService) *UserDataHandler {
+	return &UserDataHandler{
+		userData:  userData,
+		retention: retention,
+	}
+}
+
+// ExportMyData godoc
+// @Summary 导出个人数据
+// @Description 以 zip 压缩包（内含 export.json）流式返回与当前用户关联的全部数据：资料、邮箱、邮件头信息、域名、Webhook（密钥已遮盖）、标签、API Key 元数据、组织成员关系和分发列表。includeBodies=true 时包含邮件正文。每个用户每小时最多导出一次
+// @Tags Auth
+// @Produce application/zip
+// @Security BearerAuth
+// @Param includeBodies query bool false "是否包含邮件正文"
+// @Success 200 {file} file
+// @Failure 401 {object} Response
+// @Failure 429 {object} Response
+// @Failure 500 {object} Response
+// @Router /v1/auth/me/data-export [get]
+func (h *UserDataHandler) ExportMyData(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	export, err := h.userData.Export(c.Request.Context(), userID, c.Query("includeBodies") == "true")
+	if err != nil {
+		if errors.Is(err, service.ErrExportRateLimited) {
+			next := h.userData.NextExportAt(userID)
+			middleware.RespondThrottled(c, middleware.Throttle{
+				Status:     http.StatusTooManyRequests,
+				ErrorCode:  middleware.ErrorCodeQuotaExceeded,
+				Message:    MsgDataExportRateLimited,
+				RetryAfter: time.Until(next),
+				Window:     &middleware.QuotaWindow{Limit: 1, Remaining: 0, Reset: next},
+			})
+			return
+		}
+		InternalError(c, MsgDataExportFailed)
+		return
+	}
+
+	filename := fmt.Sprintf("tempmail-export-%s.zip", export.ExportedAt.UTC().Format("20060102T150405Z"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	// 已开始写响应，出错时只能中断连接
+	if err := service.WriteArchive(c.Request.Context(), c.Writer, export); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// RetentionReport godoc
+// @Summary 数据保留报告
+// @Description 按数据类别汇总最早保留的记录、按年龄分桶的数量以及配置的保留期限当前是否满足（只含计数，不含用户数据）
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=service.RetentionReport}
+// @Failure 403 {object} Response
+// @Failure 500 {object} Response
+// @Router /v1/admin/reports/retention [get]
+func (h *UserDataHandler) RetentionReport(c *gin.Context) {
+	report, err := h.retention.Report(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		InternalError(c, MsgRetentionReportFailed)
+		return
+	}
+
+	Success(c, report)
+}
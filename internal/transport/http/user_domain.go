@@ -1,6 +1,8 @@
 package httptransport
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"tempmail/backend/internal/domain"
@@ -21,8 +23,9 @@ func NewUserDomainHandler(service *service.UserDomainService) *UserDomainHandler
 
 // AddUserDomainRequest 添加用户域名请求
 type AddUserDomainRequest struct {
-	Domain string `json:"domain" binding:"required"`
-	Mode   string `json:"mode" binding:"required,oneof=shared exclusive catch_all whitelist"`
+	Domain string  `json:"domain" binding:"required"`
+	Mode   string  `json:"mode" binding:"required,oneof=shared exclusive catch_all whitelist"`
+	OrgID  *string `json:"orgId"` // 可选：添加为组织域名（需要组织 owner 角色）
 }
 
 // AddDomain godoc
@@ -56,17 +59,21 @@ func (h *UserDomainHandler) AddDomain(c *gin.Context) {
 
 	input := service.AddDomainInput{
 		UserID: userID,
+		OrgID:  req.OrgID,
 		Domain: req.Domain,
 		Mode:   mode,
 	}
 
-	userDomain, err := h.service.AddDomain(input)
+	userDomain, err := h.service.AddDomain(c.Request.Context(), input)
 	if err != nil {
+		if respondOrgError(c, err) {
+			return
+		}
 		switch err {
 		case service.ErrInvalidDomain:
 			BadRequest(c, GetErrorMessage(service.ErrInvalidDomain))
-		case service.ErrDomainAlreadyExists:
-			Conflict(c, GetErrorMessage(service.ErrDomainAlreadyExists))
+		case service.ErrDomainAlreadyExists, service.ErrDomainIsSystem:
+			Conflict(c, GetErrorMessage(err))
 		default:
 			InternalError(c, MsgDomainAddFailed)
 		}
@@ -91,7 +98,7 @@ func (h *UserDomainHandler) ListDomains(c *gin.Context) {
 		return
 	}
 
-	domains, err := h.service.ListUserDomains(userID)
+	domains, err := h.service.ListUserDomains(c.Request.Context(), userID)
 	if err != nil {
 		InternalError(c, MsgDomainListFailed)
 		return
@@ -125,7 +132,7 @@ func (h *UserDomainHandler) GetDomain(c *gin.Context) {
 
 	domainID := c.Param("id")
 
-	userDomain, err := h.service.GetUserDomain(domainID, userID)
+	userDomain, err := h.service.GetUserDomain(c.Request.Context(), domainID, userID)
 	if err != nil {
 		switch err {
 		case service.ErrDomainNotFound:
@@ -162,7 +169,7 @@ func (h *UserDomainHandler) VerifyDomain(c *gin.Context) {
 
 	domainID := c.Param("id")
 
-	userDomain, err := h.service.VerifyDomain(domainID, userID)
+	userDomain, err := h.service.VerifyDomain(c.Request.Context(), domainID, userID)
 	if err != nil {
 		switch err {
 		case service.ErrDomainNotFound:
@@ -200,7 +207,7 @@ func (h *UserDomainHandler) GetSetupInstructions(c *gin.Context) {
 
 	domainID := c.Param("id")
 
-	instructions, err := h.service.GetDomainSetupInstructions(domainID, userID)
+	instructions, err := h.service.GetDomainSetupInstructions(c.Request.Context(), domainID, userID)
 	if err != nil {
 		switch err {
 		case service.ErrDomainNotFound:
@@ -216,14 +223,15 @@ func (h *UserDomainHandler) GetSetupInstructions(c *gin.Context) {
 	Success(c, instructions)
 }
 
-// UpdateDomainModeRequest 更新域名模式请求
+// UpdateDomainModeRequest 更新域名请求（模式和/或到期时间，至少提供一项）
 type UpdateDomainModeRequest struct {
-	Mode string `json:"mode" binding:"required,oneof=shared exclusive catch_all whitelist"`
+	Mode      string     `json:"mode" binding:"omitempty,oneof=shared exclusive catch_all whitelist"`
+	ExpiresAt *time.Time `json:"expiresAt"` // 续期：新的到期时间，续期后立即恢复正常
 }
 
 // UpdateDomainMode godoc
-// @Summary 更新域名模式
-// @Description 更新域名的共享/独享模式
+// @Summary 更新域名
+// @Description 更新域名的共享/独享模式，或续期（设置新的到期时间）
 // @Tags User Domains
 // @Accept json
 // @Produce json
@@ -250,15 +258,27 @@ func (h *UserDomainHandler) UpdateDomainMode(c *gin.Context) {
 		return
 	}
 
-	mode := domain.DomainMode(req.Mode)
+	if req.Mode == "" && req.ExpiresAt == nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
 
-	userDomain, err := h.service.UpdateDomainMode(domainID, userID, mode)
+	var userDomain *domain.UserDomain
+	var err error
+	if req.ExpiresAt != nil {
+		userDomain, err = h.service.RenewDomain(c.Request.Context(), domainID, userID, *req.ExpiresAt)
+	}
+	if err == nil && req.Mode != "" {
+		userDomain, err = h.service.UpdateDomainMode(c.Request.Context(), domainID, userID, domain.DomainMode(req.Mode))
+	}
 	if err != nil {
 		switch err {
 		case service.ErrDomainNotFound:
 			NotFound(c, GetErrorMessage(service.ErrDomainNotFound))
 		case service.ErrNotDomainOwner:
 			Forbidden(c, "无权操作此域名")
+		case service.ErrInvalidExpiry:
+			BadRequest(c, GetErrorMessage(err))
 		default:
 			InternalError(c, MsgDomainUpdateFailed)
 		}
@@ -288,7 +308,7 @@ func (h *UserDomainHandler) DeleteDomain(c *gin.Context) {
 
 	domainID := c.Param("id")
 
-	err := h.service.DeleteUserDomain(domainID, userID)
+	err := h.service.DeleteUserDomain(c.Request.Context(), domainID, userID)
 	if err != nil {
 		switch err {
 		case service.ErrDomainNotFound:
@@ -0,0 +1,77 @@
+package httptransport
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+)
+
+// UserSpamHandler 用户级垃圾邮件阈值处理器
+type UserSpamHandler struct {
+	spam *service.UserSpamService
+}
+
+// NewUserSpamHandler 创建用户垃圾邮件阈值处理器
+func NewUserSpamHandler(spam *service.UserSpamService) *UserSpamHandler {
+	return &UserSpamHandler{spam: spam}
+}
+
+type spamThresholdsRequest struct {
+	SpamQuarantineScore *float64 `json:"spamQuarantineScore"` // 软阈值，0 表示恢复系统默认
+	SpamRejectScore     *float64 `json:"spamRejectScore"`     // 硬阈值，0 表示恢复系统默认
+}
+
+type spamThresholdsResponse struct {
+	SpamQuarantineScore *float64 `json:"spamQuarantineScore,omitempty"`
+	SpamRejectScore     *float64 `json:"spamRejectScore,omitempty"`
+}
+
+// UpdateThresholds godoc
+// @Summary 设置垃圾邮件阈值
+// @Description 设置当前用户名下邮箱的默认垃圾邮件阈值（邮箱自定义阈值优先），不能超过系统配置的上限，0 表示恢复系统默认
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body spamThresholdsRequest true "垃圾邮件阈值"
+// @Success 200 {object} spamThresholdsResponse
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Router /v1/auth/me/spam-thresholds [put]
+func (h *UserSpamHandler) UpdateThresholds(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		Unauthorized(c, MsgAuthRequired)
+		return
+	}
+
+	var req spamThresholdsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.SpamQuarantineScore == nil && req.SpamRejectScore == nil) {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	user, err := h.spam.UpdateThresholds(c.Request.Context(), userID, service.UpdateSpamThresholdsInput{
+		QuarantineScore: req.SpamQuarantineScore,
+		RejectScore:     req.SpamRejectScore,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSpamThresholdInvalid):
+			BadRequest(c, GetErrorMessage(err))
+		case errors.Is(err, memory.ErrUserNotFound):
+			NotFound(c, MsgUserNotFound)
+		default:
+			InternalError(c, MsgInternalError)
+		}
+		return
+	}
+
+	Success(c, spamThresholdsResponse{
+		SpamQuarantineScore: user.SpamQuarantineScore,
+		SpamRejectScore:     user.SpamRejectScore,
+	})
+}
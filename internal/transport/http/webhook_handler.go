@@ -1,10 +1,12 @@
 package httptransport
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
 
+	"tempmail/backend/internal/security"
 	"tempmail/backend/internal/service"
 )
 
@@ -12,7 +14,7 @@ import (
 
 // createWebhook godoc
 // @Summary 创建 Webhook
-// @Description 创建一个新的 Webhook 配置
+// @Description 创建一个新的 Webhook 配置。backfill=true 时立即补发最近几分钟内收到的邮件（mail.received，data.replayed=true，按 messageId 去重）
 // @Tags Webhooks
 // @Accept json
 // @Produce json
@@ -38,8 +40,11 @@ func (h *Handler) createWebhook(c *gin.Context) {
 	}
 	input.UserID = userID.(string)
 
-	webhook, err := h.webhook.CreateWebhook(input)
+	webhook, err := h.webhook.CreateWebhook(c.Request.Context(), input)
 	if err != nil {
+		if respondOrgError(c, err) || respondWebhookPayloadError(c, err) {
+			return
+		}
 		InternalError(c, "创建 Webhook 失败")
 		return
 	}
@@ -65,7 +70,7 @@ func (h *Handler) listWebhooks(c *gin.Context) {
 		return
 	}
 
-	webhooks, err := h.webhook.ListWebhooks(userID.(string))
+	webhooks, err := h.webhook.ListWebhooks(c.Request.Context(), userID.(string))
 	if err != nil {
 		InternalError(c, "获取 Webhook 列表失败")
 		return
@@ -89,7 +94,7 @@ func (h *Handler) listWebhooks(c *gin.Context) {
 func (h *Handler) getWebhook(c *gin.Context) {
 	id := c.Param("id")
 
-	webhook, err := h.webhook.GetWebhook(id)
+	webhook, err := h.webhook.GetWebhook(c.Request.Context(), id)
 	if err != nil {
 		NotFound(c, "Webhook 不存在")
 		return
@@ -97,7 +102,7 @@ func (h *Handler) getWebhook(c *gin.Context) {
 
 	// 验证权限
 	userID, _ := c.Get("userID")
-	if webhook.UserID != userID.(string) {
+	if !h.authz.Can(c.Request.Context(), userID.(string), webhook.UserID, webhook.OrgID, service.ActionRead) {
 		Forbidden(c, "无权访问")
 		return
 	}
@@ -123,14 +128,14 @@ func (h *Handler) updateWebhook(c *gin.Context) {
 	id := c.Param("id")
 
 	// 验证权限
-	webhook, err := h.webhook.GetWebhook(id)
+	webhook, err := h.webhook.GetWebhook(c.Request.Context(), id)
 	if err != nil {
 		NotFound(c, "Webhook 不存在")
 		return
 	}
 
 	userID, _ := c.Get("userID")
-	if webhook.UserID != userID.(string) {
+	if !h.authz.Can(c.Request.Context(), userID.(string), webhook.UserID, webhook.OrgID, service.ActionWrite) {
 		Forbidden(c, "无权访问")
 		return
 	}
@@ -141,8 +146,11 @@ func (h *Handler) updateWebhook(c *gin.Context) {
 		return
 	}
 
-	updated, err := h.webhook.UpdateWebhook(id, input)
+	updated, err := h.webhook.UpdateWebhook(c.Request.Context(), id, input)
 	if err != nil {
+		if respondWebhookPayloadError(c, err) {
+			return
+		}
 		InternalError(c, "更新 Webhook 失败")
 		return
 	}
@@ -166,19 +174,19 @@ func (h *Handler) deleteWebhook(c *gin.Context) {
 	id := c.Param("id")
 
 	// 验证权限
-	webhook, err := h.webhook.GetWebhook(id)
+	webhook, err := h.webhook.GetWebhook(c.Request.Context(), id)
 	if err != nil {
 		NotFound(c, "Webhook 不存在")
 		return
 	}
 
 	userID, _ := c.Get("userID")
-	if webhook.UserID != userID.(string) {
+	if !h.authz.Can(c.Request.Context(), userID.(string), webhook.UserID, webhook.OrgID, service.ActionWrite) {
 		Forbidden(c, "无权访问")
 		return
 	}
 
-	if err := h.webhook.DeleteWebhook(id); err != nil {
+	if err := h.webhook.DeleteWebhook(c.Request.Context(), id); err != nil {
 		InternalError(c, "删除 Webhook 失败")
 		return
 	}
@@ -203,14 +211,14 @@ func (h *Handler) getWebhookDeliveries(c *gin.Context) {
 	id := c.Param("id")
 
 	// 验证权限
-	webhook, err := h.webhook.GetWebhook(id)
+	webhook, err := h.webhook.GetWebhook(c.Request.Context(), id)
 	if err != nil {
 		NotFound(c, "Webhook 不存在")
 		return
 	}
 
 	userID, _ := c.Get("userID")
-	if webhook.UserID != userID.(string) {
+	if !h.authz.Can(c.Request.Context(), userID.(string), webhook.UserID, webhook.OrgID, service.ActionRead) {
 		Forbidden(c, "无权访问")
 		return
 	}
@@ -221,7 +229,7 @@ func (h *Handler) getWebhookDeliveries(c *gin.Context) {
 		fmt.Sscanf(limitStr, "%d", &limit)
 	}
 
-	deliveries, err := h.webhook.GetDeliveries(id, limit)
+	deliveries, err := h.webhook.GetDeliveries(c.Request.Context(), id, limit)
 	if err != nil {
 		InternalError(c, "获取投递记录失败")
 		return
@@ -229,3 +237,272 @@ func (h *Handler) getWebhookDeliveries(c *gin.Context) {
 
 	Success(c, deliveries)
 }
+
+// listWebhookDeadLetters godoc
+// @Summary 列出死信
+// @Description 列出 Webhook 重试用尽、尚未重新投递的投递记录（新的在前）
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param limit query int false "记录数量（默认20，最大100）"
+// @Success 200 {object} Response{data=[]domain.WebhookDelivery}
+// @Failure 404 {object} errorResponse
+// @Security BearerAuth
+// @Router /v1/webhooks/{id}/dead-letters [get]
+func (h *Handler) listWebhookDeadLetters(c *gin.Context) {
+	id := c.Param("id")
+
+	webhook, err := h.webhook.GetWebhook(c.Request.Context(), id)
+	if err != nil {
+		NotFound(c, "Webhook 不存在")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if !h.authz.Can(c.Request.Context(), userID.(string), webhook.UserID, webhook.OrgID, service.ActionRead) {
+		Forbidden(c, "无权访问")
+		return
+	}
+
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		fmt.Sscanf(limitStr, "%d", &limit)
+	}
+
+	deliveries, err := h.webhook.ListDeadLetters(c.Request.Context(), id, limit)
+	if err != nil {
+		InternalError(c, "获取投递记录失败")
+		return
+	}
+
+	Success(c, deliveries)
+}
+
+// redeliverWebhookDelivery godoc
+// @Summary 重新投递
+// @Description 用 Webhook 当前的回调地址和签名密钥同步重新发送投递记录中的回调内容，返回新的投递记录。
+// @Description 新投递失败时按正常计划重试；原记录标记为已重新投递，不再列入死信
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param deliveryId path string true "投递记录 ID"
+// @Success 200 {object} Response{data=domain.WebhookDelivery}
+// @Failure 400 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Security BearerAuth
+// @Router /v1/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *Handler) redeliverWebhookDelivery(c *gin.Context) {
+	webhook, err := h.webhook.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		NotFound(c, "Webhook 不存在")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if !h.authz.Can(c.Request.Context(), userID.(string), webhook.UserID, webhook.OrgID, service.ActionWrite) {
+		Forbidden(c, "无权访问")
+		return
+	}
+
+	delivery, err := h.webhook.Redeliver(c.Request.Context(), webhook, c.Param("deliveryId"))
+	switch {
+	case err == nil:
+		Success(c, delivery)
+	case errors.Is(err, service.ErrWebhookDeliveryNotFound):
+		NotFound(c, GetErrorMessage(err))
+	case errors.Is(err, service.ErrWebhookDeliveryNoPayload):
+		BadRequest(c, GetErrorMessage(err))
+	default:
+		InternalError(c, MsgInternalError)
+	}
+}
+
+// testWebhookRequest 测试投递请求
+type testWebhookRequest struct {
+	Event string `json:"event"` // 示例事件类型，默认为订阅的第一个事件
+}
+
+// testWebhook godoc
+// @Summary 测试 Webhook
+// @Description 按 Webhook 的回调内容格式同步发送一次示例事件（test=true，请求带 X-Webhook-Test: true），返回投递结果。
+// @Description 测试投递记入投递记录，但不经过熔断器，失败也不重试
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param request body testWebhookRequest false "示例事件类型"
+// @Success 200 {object} Response{data=domain.WebhookDelivery}
+// @Failure 400 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Security BearerAuth
+// @Router /v1/webhooks/{id}/test [post]
+func (h *Handler) testWebhook(c *gin.Context) {
+	webhook, err := h.webhook.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		NotFound(c, "Webhook 不存在")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if !h.authz.Can(c.Request.Context(), userID.(string), webhook.UserID, webhook.OrgID, service.ActionWrite) {
+		Forbidden(c, "无权访问")
+		return
+	}
+
+	var req testWebhookRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, MsgInvalidRequest)
+			return
+		}
+	}
+
+	delivery, err := h.webhook.TestWebhook(c.Request.Context(), webhook, req.Event)
+	if err != nil {
+		if respondWebhookPayloadError(c, err) {
+			return
+		}
+		InternalError(c, MsgInternalError)
+		return
+	}
+
+	Success(c, delivery)
+}
+
+// respondWebhookPayloadError 回调内容格式、模板和测试事件错误返回 400，其他错误返回 false
+func respondWebhookPayloadError(c *gin.Context, err error) bool {
+	var tplErr *service.WebhookTemplateError
+	switch {
+	case errors.As(err, &tplErr):
+		BadRequest(c, GetErrorMessage(service.ErrWebhookTemplateInvalid)+"："+tplErr.Err.Error())
+	case errors.Is(err, service.ErrWebhookPayloadFormat), errors.Is(err, service.ErrWebhookTestEvent),
+		errors.Is(err, service.ErrWebhookEventInvalid):
+		BadRequest(c, GetErrorMessage(err))
+	default:
+		return false
+	}
+	return true
+}
+
+// listWebhookBreakers godoc
+// @Summary Webhook 熔断状态
+// @Description 列出当前熔断中（open / half_open）的 Webhook 目标主机：熔断开始时间、下次探测时间、连续失败次数和被短路的投递数
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} Response{data=[]service.BreakerStatus}
+// @Failure 403 {object} errorResponse
+// @Security BearerAuth
+// @Router /v1/admin/webhooks/breakers [get]
+func (h *Handler) listWebhookBreakers(c *gin.Context) {
+	breakers := h.webhook.Breaker().List()
+	Success(c, gin.H{
+		"items": breakers,
+		"count": len(breakers),
+	})
+}
+
+// listAllWebhookDeadLetters godoc
+// @Summary Webhook 死信
+// @Description 列出所有 Webhook 重试用尽、尚未重新投递的投递记录（新的在前）
+// @Tags Admin
+// @Produce json
+// @Param limit query int false "记录数量（默认20，最大100）"
+// @Success 200 {object} Response{data=[]domain.WebhookDelivery}
+// @Failure 403 {object} errorResponse
+// @Security BearerAuth
+// @Router /v1/admin/webhooks/dead-letters [get]
+func (h *Handler) listAllWebhookDeadLetters(c *gin.Context) {
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		fmt.Sscanf(limitStr, "%d", &limit)
+	}
+
+	deliveries, err := h.webhook.ListDeadLetters(c.Request.Context(), "", limit)
+	if err != nil {
+		InternalError(c, "获取投递记录失败")
+		return
+	}
+
+	Success(c, gin.H{
+		"items": deliveries,
+		"count": len(deliveries),
+	})
+}
+
+// ========== Mailbox Webhook Handlers ==========
+
+// createMailboxWebhook godoc
+// @Summary 创建邮箱 Webhook
+// @Description 凭邮箱令牌为该邮箱注册回调（无需登录）：只接收该邮箱的 mail.received / mailbox.expired / message.deleted / quota.exceeded 事件，随邮箱删除或过期自动删除。回调地址必须是公网 HTTP(S) 地址，数量上限和重试计划见系统配置 guestWebhooks
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Param webhook body service.CreateMailboxWebhookInput true "Webhook 信息"
+// @Success 201 {object} Response{data=domain.Webhook}
+// @Failure 400 {object} errorResponse
+// @Failure 409 {object} errorResponse
+// @Router /v1/mailboxes/{id}/webhooks [post]
+func (h *Handler) createMailboxWebhook(c *gin.Context) {
+	var input service.CreateMailboxWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		BadRequest(c, MsgInvalidRequest)
+		return
+	}
+
+	webhook, err := h.webhook.CreateMailboxWebhook(c.Request.Context(), c.Param("id"), input)
+	switch {
+	case err == nil:
+		Created(c, webhook)
+	case errors.Is(err, service.ErrMailboxWebhookLimit):
+		Conflict(c, GetErrorMessage(service.ErrMailboxWebhookLimit))
+	case errors.Is(err, service.ErrMailboxWebhookEvent), errors.Is(err, security.ErrUnsafeURL):
+		BadRequest(c, GetErrorMessage(err))
+	case respondWebhookPayloadError(c, err):
+	default:
+		InternalError(c, MsgMailboxWebhookCreateFailed)
+	}
+}
+
+// listMailboxWebhooks godoc
+// @Summary 列出邮箱 Webhook
+// @Description 列出凭邮箱令牌创建的 Webhook
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "邮箱ID"
+// @Success 200 {object} Response{data=[]domain.Webhook}
+// @Failure 500 {object} errorResponse
+// @Router /v1/mailboxes/{id}/webhooks [get]
+func (h *Handler) listMailboxWebhooks(c *gin.Context) {
+	webhooks, err := h.webhook.ListMailboxWebhooks(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		InternalError(c, MsgMailboxWebhookListFailed)
+		return
+	}
+
+	Success(c, gin.H{
+		"items": webhooks,
+		"count": len(webhooks),
+	})
+}
+
+// deleteMailboxWebhook godoc
+// @Summary 删除邮箱 Webhook
+// @Description 删除凭邮箱令牌创建的 Webhook
+// @Tags Webhooks
+// @Param id path string true "邮箱ID"
+// @Param webhookId path string true "Webhook ID"
+// @Success 204
+// @Failure 404 {object} errorResponse
+// @Router /v1/mailboxes/{id}/webhooks/{webhookId} [delete]
+func (h *Handler) deleteMailboxWebhook(c *gin.Context) {
+	err := h.webhook.DeleteMailboxWebhook(c.Request.Context(), c.Param("id"), c.Param("webhookId"))
+	switch {
+	case err == nil:
+		NoContent(c)
+	case errors.Is(err, service.ErrWebhookNotFound):
+		NotFound(c, GetErrorMessage(service.ErrWebhookNotFound))
+	default:
+		InternalError(c, MsgMailboxWebhookDeleteFailed)
+	}
+}
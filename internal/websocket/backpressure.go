@@ -0,0 +1,189 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"tempmail/backend/pkg/wsproto"
+)
+
+// 发送队列背压
+//
+// 每个连接有一个有界发送队列，写协程把队列中的消息写到网络。客户端读取过慢时入队失败，事件被丢弃；
+// Hub 记录每个连接的丢弃数和连续阻塞时长。自上次成功入队以来丢弃超过 MaxDropped 条，或连续阻塞超过
+// MaxBlocked 时驱逐该连接：清空队列，只留一条 code=overflow 的错误消息，由写协程写出后以
+// CloseSlowConsumer 断开。客户端重连后带上恢复游标重新订阅即可补齐事件，而不是在连接上悄悄缺失事件。
+//
+// 驱逐只撤销订阅、通知写协程，send 通道仍由注销流程关闭，与正常断开走同一条路径。
+
+const (
+	DefaultSendBufferSize = 256              // 每个连接的发送队列长度
+	DefaultMaxDropped     = 50               // 连续丢弃多少条事件后驱逐
+	DefaultMaxBlocked     = 30 * time.Second // 发送队列连续阻塞多久后驱逐
+)
+
+// SendPolicy 发送队列大小和慢客户端驱逐阈值（零值字段使用默认值）
+type SendPolicy struct {
+	BufferSize int
+	MaxDropped int
+	MaxBlocked time.Duration
+}
+
+// withDefaults 补全未设置的字段
+func (p SendPolicy) withDefaults() SendPolicy {
+	if p.BufferSize <= 0 {
+		p.BufferSize = DefaultSendBufferSize
+	}
+	if p.MaxDropped <= 0 {
+		p.MaxDropped = DefaultMaxDropped
+	}
+	if p.MaxBlocked <= 0 {
+		p.MaxBlocked = DefaultMaxBlocked
+	}
+	return p
+}
+
+// SendMetrics 发送队列指标
+type SendMetrics interface {
+	RecordWebSocketDropped()
+	RecordWebSocketEvicted()
+}
+
+// ClientStats 连接的发送队列状态
+type ClientStats struct {
+	ID            string        `json:"id"`
+	UserID        string        `json:"userId,omitempty"`
+	MailboxID     string        `json:"mailboxId,omitempty"`
+	Subscriptions int           `json:"subscriptions"`
+	QueueDepth    int           `json:"queueDepth"`    // 队列中待写出的消息数
+	QueueCapacity int           `json:"queueCapacity"` // 队列长度
+	Dropped       int64         `json:"dropped"`       // 连接建立以来丢弃的消息数
+	BlockedFor    time.Duration `json:"blockedFor"`    // 当前连续阻塞时长，未阻塞为 0
+	Evicted       bool          `json:"evicted"`       // 已因读取过慢被驱逐，等待注销
+}
+
+// SetSendPolicy 设置之后建立的连接使用的发送队列大小和驱逐阈值
+func (h *Hub) SetSendPolicy(policy SendPolicy) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sendPolicy = policy.withDefaults()
+}
+
+// SetMetrics 设置发送队列指标
+func (h *Hub) SetMetrics(metrics SendMetrics) {
+	h.metrics = metrics
+}
+
+// ClientStats 返回所有连接的发送队列状态（按连接ID排序）
+func (h *Hub) ClientStats() []ClientStats {
+	now := time.Now()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := make([]ClientStats, 0, len(h.clients))
+	for _, client := range h.clients {
+		client.mu.RLock()
+		stat := ClientStats{
+			ID:            client.ID,
+			UserID:        client.UserID,
+			MailboxID:     client.MailboxID,
+			Subscriptions: len(client.mailboxIDs),
+			QueueDepth:    len(client.send),
+			QueueCapacity: cap(client.send),
+			Dropped:       client.dropped,
+			Evicted:       client.closeCode == wsproto.CloseSlowConsumer,
+		}
+		if !client.blockedSince.IsZero() {
+			stat.BlockedFor = now.Sub(client.blockedSince)
+		}
+		client.mu.RUnlock()
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
+}
+
+// push 非阻塞入队，失败时计入丢弃
+//
+// 返回自上次成功入队以来丢弃的条数和连续阻塞时长，入队成功时都为 0。
+func (c *Client) push(payload []byte, now time.Time) (pending int, blocked time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case c.send <- payload:
+		c.pendingDrops, c.blockedSince = 0, time.Time{}
+		return 0, 0
+	default:
+	}
+	c.dropped++
+	c.pendingDrops++
+	if c.blockedSince.IsZero() {
+		c.blockedSince = now
+	}
+	return c.pendingDrops, now.Sub(c.blockedSince)
+}
+
+// enqueue 向客户端发送消息，客户端超过驱逐阈值时断开（调用方持有 h.mu 写锁）
+func (h *Hub) enqueue(client *Client, payload []byte) {
+	pending, blocked := client.push(payload, time.Now())
+	if pending == 0 {
+		return
+	}
+	if h.metrics != nil {
+		h.metrics.RecordWebSocketDropped()
+	}
+	if pending >= h.sendPolicy.MaxDropped || blocked >= h.sendPolicy.MaxBlocked {
+		h.evict(client, pending, blocked)
+		return
+	}
+	h.log.Warn("client channel blocked, event dropped",
+		zap.String("clientID", client.ID),
+		zap.Int("pending", pending),
+		zap.Duration("blocked", blocked))
+}
+
+// evict 驱逐读取过慢的客户端（调用方持有 h.mu 写锁）
+//
+// 撤销订阅后清空队列，只留一条 overflow 错误，由写协程写出后以 CloseSlowConsumer 断开；
+// 客户端仍留在 clients 中，读协程随连接关闭退出后照常注销，send 通道只在注销时关闭。
+func (h *Hub) evict(client *Client, pending int, blocked time.Duration) {
+	if !h.revokeSession(client, wsproto.CloseSlowConsumer, "send queue overflow") {
+		return
+	}
+
+	for drained := false; !drained; {
+		select {
+		case <-client.send:
+		default:
+			drained = true
+		}
+	}
+	payload, err := json.Marshal(&wsproto.Message{
+		Type:      wsproto.MessageTypeError,
+		Code:      wsproto.ErrCodeOverflow,
+		Error:     "send queue overflow, reconnect and resubscribe with sinceSeq to resume",
+		Timestamp: time.Now(),
+	})
+	if err == nil {
+		select {
+		case client.send <- payload:
+		default:
+		}
+	}
+	if client.overflow != nil {
+		close(client.overflow)
+	}
+
+	if h.metrics != nil {
+		h.metrics.RecordWebSocketEvicted()
+	}
+	h.log.Warn("slow websocket client evicted",
+		zap.String("clientID", client.ID),
+		zap.String("userID", client.UserID),
+		zap.Int("dropped", pending),
+		zap.Duration("blocked", blocked))
+}
@@ -0,0 +1,229 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/pkg/wsclient"
+	"tempmail/backend/pkg/wsproto"
+)
+
+// startHubServer 启动运行中的 Hub 和 /v1/ws 端点，返回 ws:// 地址
+func startHubServer(t *testing.T, store MailboxStore) (*Hub, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	hub := NewHub(nil, nil, store)
+	hub.SetReplayWindow(5 * time.Minute)
+	go hub.Run(t.Context())
+
+	engine := gin.New()
+	engine.GET("/v1/ws", HandleWebSocket(hub))
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return hub, "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/ws"
+}
+
+func nextEvent(t *testing.T, c *wsclient.Client) wsclient.Event {
+	t.Helper()
+	select {
+	case event, ok := <-c.Events():
+		require.True(t, ok, "事件通道已关闭: %v", c.Err())
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有收到事件")
+		return wsclient.Event{}
+	}
+}
+
+// dropConnections 服务端断开所有连接（模拟网络中断）
+func dropConnections(hub *Hub) {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	for _, client := range hub.clients {
+		_ = client.conn.Close()
+	}
+}
+
+func TestHub_WSClient(t *testing.T) {
+	store := publicMailboxStore{"mb-1": {ID: "mb-1", Token: "tok-1"}}
+	hub, url := startHubServer(t, store)
+
+	client, err := wsclient.Connect(t.Context(), url, wsclient.Auth{Token: "tok-1", MailboxID: "mb-1"})
+	require.NoError(t, err)
+	client.SetReconnectBackoff(200*time.Millisecond, time.Second)
+
+	t.Run("订阅时协商协议版本", func(t *testing.T) {
+		require.NoError(t, client.Subscribe("mb-1", 0))
+		subscribed := nextEvent(t, client)
+		assert.Equal(t, wsproto.MessageTypeSubscribed, subscribed.Type)
+		assert.Equal(t, wsproto.Version, subscribed.ProtocolVersion)
+		assert.Equal(t, wsproto.Version, client.ProtocolVersion(), "hello 先于其他消息")
+	})
+
+	t.Run("收到新邮件", func(t *testing.T) {
+		hub.NotifyNewMail(t.Context(), "mb-1", &domain.Message{ID: "msg-1", MailboxID: "mb-1", Seq: 1, Subject: "first", CreatedAt: time.Now()})
+		event := nextEvent(t, client)
+		require.NotNil(t, event.NewMail)
+		assert.Equal(t, "msg-1", event.NewMail.MessageID)
+		assert.False(t, event.Replayed)
+	})
+
+	t.Run("断线重连后按恢复游标只补发之后的邮件", func(t *testing.T) {
+		dropConnections(hub)
+		hub.NotifyNewMail(t.Context(), "mb-1", &domain.Message{ID: "msg-2", MailboxID: "mb-1", Seq: 2, Subject: "second", CreatedAt: time.Now()})
+
+		assert.Equal(t, wsproto.MessageTypeSubscribed, nextEvent(t, client).Type)
+		event := nextEvent(t, client)
+		require.NotNil(t, event.NewMail)
+		assert.Equal(t, "msg-2", event.NewMail.MessageID, "msg-1 已收到，不再补发")
+		assert.True(t, event.Replayed)
+	})
+
+	t.Run("邮箱令牌失效后会话结束", func(t *testing.T) {
+		store["mb-1"].Token = "rotated"
+		hub.checkSessions(time.Now())
+		for range client.Events() {
+		}
+		assert.ErrorIs(t, client.Err(), wsclient.ErrSessionEnded)
+		assert.Equal(t, wsproto.CloseTokenRevoked, client.CloseCode())
+	})
+}
+
+func TestHub_IgnoresUnknownClientMessages(t *testing.T) {
+	_, url := startHubServer(t, publicMailboxStore{"mb-1": {ID: "mb-1", Token: "tok-1"}})
+
+	conn, _, err := websocket.DefaultDialer.DialContext(t.Context(), url+"?token=tok-1&mailboxId=mb-1", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	var hello wsproto.Message
+	require.NoError(t, conn.ReadJSON(&hello))
+	assert.Equal(t, wsproto.MessageTypeHello, hello.Type)
+
+	// 较新的客户端发送服务端不认识的消息类型和字段
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"set_filter","data":{"subject":"code"}}`)))
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"subscribe","mailboxId":"mb-1","data":{"protocolVersion":3,"compression":"zstd"}}`)))
+
+	var subscribed wsproto.Message
+	require.NoError(t, conn.ReadJSON(&subscribed))
+	assert.Equal(t, wsproto.MessageTypeSubscribed, subscribed.Type)
+	assert.Equal(t, wsproto.Version, subscribed.ProtocolVersion, "客户端版本更高时使用服务端最新版本")
+}
+
+// serverClient 返回 Hub 中唯一的连接
+func serverClient(t *testing.T, hub *Hub) *Client {
+	t.Helper()
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	require.Len(t, hub.clients, 1)
+	for _, client := range hub.clients {
+		return client
+	}
+	return nil
+}
+
+func TestHub_EvictsNonReadingClient(t *testing.T) {
+	store := publicMailboxStore{"mb-1": {ID: "mb-1", Token: "tok-1"}}
+	hub, url := startHubServer(t, store)
+	hub.SetSendPolicy(SendPolicy{BufferSize: 8, MaxDropped: 5})
+	baseline := runtime.NumGoroutine()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(t.Context(), url+"?token=tok-1&mailboxId=mb-1", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.WriteJSON(wsproto.Message{Type: wsproto.MessageTypeSubscribe, MailboxID: "mb-1"}))
+	require.Eventually(t, func() bool {
+		hub.mu.RLock()
+		defer hub.mu.RUnlock()
+		return len(hub.mailboxes["mb-1"]) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	client := serverClient(t, hub)
+
+	// 客户端不读取：写协程阻塞在网络上后队列写满，事件被丢弃直至驱逐
+	subject := strings.Repeat("x", 64<<10)
+	require.Eventually(t, func() bool {
+		hub.NotifyNewMail(t.Context(), "mb-1", &domain.Message{ID: "msg", MailboxID: "mb-1", Subject: subject, CreatedAt: time.Now()})
+		client.mu.RLock()
+		defer client.mu.RUnlock()
+		return client.closeCode == wsproto.CloseSlowConsumer
+	}, 10*time.Second, time.Millisecond)
+
+	stats := hub.ClientStats()
+	if len(stats) == 1 { // 注销可能已经完成
+		assert.True(t, stats[0].Evicted)
+		assert.GreaterOrEqual(t, stats[0].Dropped, int64(5))
+	}
+
+	// 开始读取后，依次收到队列中剩下的消息、overflow 错误和关闭码
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*time.Second)))
+	var overflow bool
+	for {
+		var msg wsproto.Message
+		err := conn.ReadJSON(&msg)
+		if err != nil {
+			var closeErr *websocket.CloseError
+			require.ErrorAs(t, err, &closeErr)
+			assert.Equal(t, wsproto.CloseSlowConsumer, closeErr.Code)
+			break
+		}
+		if msg.Type == wsproto.MessageTypeError {
+			assert.Equal(t, wsproto.ErrCodeOverflow, msg.Code)
+			overflow = true
+		}
+	}
+	assert.True(t, overflow, "关闭前收到 overflow 错误")
+
+	// 连接注销后不留下订阅和协程
+	require.Eventually(t, func() bool {
+		hub.mu.RLock()
+		defer hub.mu.RUnlock()
+		return len(hub.clients) == 0 && len(hub.mailboxes) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	conn.Close()
+	// assert.Eventually 在单独的协程中检查条件，这里直接轮询
+	for deadline := time.Now().Add(5 * time.Second); runtime.NumGoroutine() > baseline && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), baseline, "读写协程已退出")
+}
+
+func TestHub_WSClientReconnectsAfterEviction(t *testing.T) {
+	store := publicMailboxStore{"mb-1": {ID: "mb-1", Token: "tok-1"}}
+	hub, url := startHubServer(t, store)
+
+	client, err := wsclient.Connect(t.Context(), url, wsclient.Auth{Token: "tok-1", MailboxID: "mb-1"})
+	require.NoError(t, err)
+	client.SetReconnectBackoff(100*time.Millisecond, time.Second)
+	require.NoError(t, client.Subscribe("mb-1", 0))
+	require.Equal(t, wsproto.MessageTypeSubscribed, nextEvent(t, client).Type)
+	hub.NotifyNewMail(t.Context(), "mb-1", &domain.Message{ID: "msg-1", MailboxID: "mb-1", Seq: 1, CreatedAt: time.Now()})
+	require.NotNil(t, nextEvent(t, client).NewMail)
+
+	server := serverClient(t, hub)
+	hub.mu.Lock()
+	hub.evict(server, DefaultMaxDropped, 0)
+	hub.mu.Unlock()
+	hub.NotifyNewMail(t.Context(), "mb-1", &domain.Message{ID: "msg-2", MailboxID: "mb-1", Seq: 2, CreatedAt: time.Now()})
+
+	overflow := nextEvent(t, client)
+	assert.Equal(t, wsproto.MessageTypeError, overflow.Type)
+	assert.Equal(t, wsproto.ErrCodeOverflow, overflow.Code)
+
+	// 4006 不是终止关闭码：重连后按恢复游标补发驱逐期间的邮件
+	assert.Equal(t, wsproto.MessageTypeSubscribed, nextEvent(t, client).Type)
+	event := nextEvent(t, client)
+	require.NotNil(t, event.NewMail)
+	assert.Equal(t, "msg-2", event.NewMail.MessageID)
+	assert.True(t, event.Replayed)
+	assert.NoError(t, client.Err())
+}
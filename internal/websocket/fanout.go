@@ -0,0 +1,62 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+)
+
+// busRetryInterval 订阅总线失败后的重试间隔
+const busRetryInterval = 5 * time.Second
+
+// NewMailBus 跨实例的新邮件事件总线（混合存储下为 Redis 发布订阅）
+//
+// SMTP 和 API 分开部署或 API 有多个副本时，收信的实例经总线发布，所有实例都推送给各自的订阅者，
+// 补发窗口也在每个实例上记录。未接入 Redis 时 SubscribeNewMail 返回 storage.ErrPubSubUnavailable，Hub 只在进程内推送。
+type NewMailBus interface {
+	PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error
+	SubscribeNewMail(ctx context.Context) (<-chan *domain.Message, error)
+}
+
+// SetNewMailBus 设置跨实例新邮件事件总线（在 Run 之前调用）
+func (h *Hub) SetNewMailBus(bus NewMailBus) {
+	h.bus = bus
+}
+
+// receiveNewMail 订阅总线并推送收到的新邮件，直到 ctx 取消
+//
+// 订阅成功前（以及失败重试期间）新邮件只在本实例推送，不会因总线不可用而丢失本地通知。
+func (h *Hub) receiveNewMail(ctx context.Context) {
+	for {
+		messages, err := h.bus.SubscribeNewMail(ctx)
+		if errors.Is(err, storage.ErrPubSubUnavailable) {
+			h.log.Info("new mail bus unavailable, delivering in-process only")
+			return
+		}
+		if err != nil {
+			h.log.Warn("failed to subscribe new mail bus", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(busRetryInterval):
+				continue
+			}
+		}
+
+		h.busReady.Store(true)
+		for summary := range messages {
+			if msg := h.newMailMessage(summary); msg != nil {
+				h.broadcastToMailbox(summary.MailboxID, msg)
+			}
+		}
+		h.busReady.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/storage"
+	"tempmail/backend/pkg/wsproto"
+)
+
+// memoryBus 进程内模拟的 Redis 发布订阅
+type memoryBus struct {
+	mu          sync.Mutex
+	subscribers []chan *domain.Message
+	unavailable bool
+	publishErr  error
+}
+
+func (b *memoryBus) PublishNewMail(ctx context.Context, mailboxID string, message *domain.Message) error {
+	if b.publishErr != nil {
+		return b.publishErr
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subscribers {
+		copied := *message
+		ch <- &copied
+	}
+	return nil
+}
+
+func (b *memoryBus) SubscribeNewMail(ctx context.Context) (<-chan *domain.Message, error) {
+	if b.unavailable {
+		return nil, storage.ErrPubSubUnavailable
+	}
+	ch := make(chan *domain.Message, 16)
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, ch)
+	b.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, sub := range b.subscribers {
+			if sub == ch {
+				b.subscribers = append(b.subscribers[:i], b.subscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// newBusHub 启动接入总线的 Hub，等待订阅就绪
+func newBusHub(t *testing.T, bus NewMailBus) *Hub {
+	t.Helper()
+	hub := NewHub(nil, nil, nil)
+	hub.SetReplayWindow(time.Minute)
+	hub.SetNewMailBus(bus)
+	go hub.Run(t.Context())
+	require.Eventually(t, hub.busReady.Load, time.Second, 5*time.Millisecond)
+	return hub
+}
+
+// waitMessages 等待客户端收到 n 条消息
+func waitMessages(t *testing.T, c *Client, n int) []wsproto.Message {
+	t.Helper()
+	var messages []wsproto.Message
+	require.Eventually(t, func() bool {
+		messages = append(messages, drainMessages(t, c)...)
+		return len(messages) >= n
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	return append(messages, drainMessages(t, c)...)
+}
+
+func TestHub_NewMailBus(t *testing.T) {
+	t.Run("收信实例发布，所有实例各推送一次", func(t *testing.T) {
+		bus := &memoryBus{}
+		smtpHub, apiHub := newBusHub(t, bus), newBusHub(t, bus)
+
+		local := newTestClient(smtpHub, "mb-1")
+		local.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		remote := newTestClient(apiHub, "mb-1")
+		remote.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		drainMessages(t, local)
+		drainMessages(t, remote)
+
+		smtpHub.NotifyNewMail(t.Context(), "mb-1", &domain.Message{ID: "m-1", Seq: 7, Subject: "hello", Text: "body\n text", HTML: "<p>body</p>"})
+
+		for _, client := range []*Client{local, remote} {
+			messages := waitMessages(t, client, 1)
+			require.Len(t, messages, 1)
+			var data wsproto.NewMailData
+			require.NoError(t, json.Unmarshal(messages[0].Data, &data))
+			assert.Equal(t, "m-1", data.MessageID)
+			assert.Equal(t, int64(7), data.Seq)
+			assert.Equal(t, "body text", data.Preview)
+			assert.True(t, data.HasHTML)
+		}
+
+		// 补发窗口在每个实例上都有记录
+		late := newTestClient(apiHub, "mb-1")
+		late.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		ids, replayed := newMailIDs(t, drainMessages(t, late))
+		assert.Equal(t, []string{"m-1"}, ids)
+		assert.Equal(t, []bool{true}, replayed)
+	})
+
+	t.Run("发布失败时只推送本实例", func(t *testing.T) {
+		bus := &memoryBus{}
+		hub, other := newBusHub(t, bus), newBusHub(t, bus)
+		bus.publishErr = errors.New("connection refused")
+
+		local := newTestClient(hub, "mb-1")
+		local.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		remote := newTestClient(other, "mb-1")
+		remote.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		drainMessages(t, local)
+		drainMessages(t, remote)
+
+		hub.NotifyNewMail(t.Context(), "mb-1", &domain.Message{ID: "m-1"})
+		assert.Len(t, waitMessages(t, local, 1), 1)
+		assert.Empty(t, drainMessages(t, remote))
+	})
+
+	t.Run("未接入 Redis 时进程内推送", func(t *testing.T) {
+		hub := NewHub(nil, nil, nil)
+		hub.SetNewMailBus(&memoryBus{unavailable: true})
+		go hub.Run(t.Context())
+
+		client := newTestClient(hub, "mb-1")
+		client.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		drainMessages(t, client)
+
+		hub.NotifyNewMail(t.Context(), "mb-1", &domain.Message{ID: "m-1"})
+		assert.Len(t, waitMessages(t, client, 1), 1)
+		assert.False(t, hub.busReady.Load())
+	})
+}
@@ -9,28 +9,38 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 
+	jwtpkg "tempmail/backend/internal/auth/jwt"
 	"tempmail/backend/internal/domain"
+	"tempmail/backend/pkg/wsproto"
 )
 
 // MailboxStore 邮箱存储接口
 type MailboxStore interface {
-	GetMailbox(id string) (*domain.Mailbox, error)
-	ListMailboxesByUserID(userID string) []domain.Mailbox
+	GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error)
+	ListMailboxesByUserID(ctx context.Context, userID string) []domain.Mailbox
 }
 
-// JWTClaims JWT声明
-type JWTClaims struct {
-	UserID string `json:"sub"`
-	Email  string `json:"email"`
-	Tier   string `json:"tier"`
-	jwt.RegisteredClaims
+// OrgMailboxStore 组织邮箱查询（可选，存储实现时 JWT 用户可订阅所在组织的邮箱）
+type OrgMailboxStore interface {
+	ListOrgMembershipsByUserID(ctx context.Context, userID string) ([]*domain.OrgMember, error)
+	ListMailboxesByOrgID(ctx context.Context, orgID string) []domain.Mailbox
+}
+
+// ActivityRecorder 记录邮箱访问（订阅、心跳响应算作访问，用于闲置检测；实现方负责合并写入）
+type ActivityRecorder interface {
+	Touch(ctx context.Context, mailboxID string) error
+}
+
+// TokenValidator 用户访问令牌验证（与 HTTP 接口共用同一个 JWT 管理器，密钥轮换和黑名单检查只有一处）
+type TokenValidator interface {
+	Authenticate(ctx context.Context, tokenString string) (*jwtpkg.Claims, error)
 }
 
 // upgraderFactory 创建带有 Origin 验证的 WebSocket 升级器
@@ -65,29 +75,6 @@ func upgraderFactory(allowedOrigins []string) websocket.Upgrader {
 	}
 }
 
-// MessageType 定义WebSocket消息类型
-type MessageType string
-
-const (
-	MessageTypeNewMail       MessageType = "new_mail"
-	MessageTypeMailboxUpdate MessageType = "mailbox_update"
-	MessageTypePing          MessageType = "ping"
-	MessageTypePong          MessageType = "pong"
-	MessageTypeSubscribe     MessageType = "subscribe"
-	MessageTypeUnsubscribe   MessageType = "unsubscribe"
-	MessageTypeSubscribed    MessageType = "subscribed"
-	MessageTypeError         MessageType = "error"
-)
-
-// Message 定义WebSocket消息结构
-type Message struct {
-	Type      MessageType     `json:"type"`
-	MailboxID string          `json:"mailboxId,omitempty"`
-	Data      json.RawMessage `json:"data,omitempty"`
-	Error     string          `json:"error,omitempty"`
-	Timestamp time.Time       `json:"timestamp"`
-}
-
 // Client 代表一个WebSocket客户端连接
 type Client struct {
 	ID         string
@@ -103,6 +90,19 @@ type Client struct {
 	Token       string   // 原始token
 	IsMailbox   bool     // 是否是邮箱token认证
 	Permissions []string // 可访问的邮箱ID列表
+	// 通过公开权限订阅的邮箱（无需令牌，只读；取消公开时撤销）
+	publicIDs map[string]bool
+	// 会话有效期（见 session.go）：零值表示不过期
+	expiresAt    time.Time
+	expiringSent bool   // 已推送 auth_expiring
+	closeCode    int    // 服务端主动断开时的关闭码
+	closeReason  string // 关闭原因
+
+	// 发送队列背压（见 backpressure.go），计数由 mu 保护
+	overflow     chan struct{} // 被驱逐时关闭，写协程写出 overflow 错误后断开
+	dropped      int64         // 连接建立以来丢弃的消息数
+	pendingDrops int           // 自上次成功入队以来丢弃的消息数
+	blockedSince time.Time     // 开始连续入队失败的时间，零值表示未阻塞
 }
 
 // Hub 管理所有WebSocket连接
@@ -116,26 +116,36 @@ type Hub struct {
 	log            *zap.Logger
 	allowedOrigins []string // 允许的 Origin 列表
 	// 认证相关
-	jwtSecret    string       // JWT密钥
-	mailboxStore MailboxStore // 邮箱存储接口
+	tokens       TokenValidator // 用户令牌验证
+	mailboxStore MailboxStore   // 邮箱存储接口
+	activity     ActivityRecorder // 邮箱访问记录（可选）
+	// 订阅补发：每个邮箱最近的新邮件事件
+	replayWindow time.Duration
+	recent       map[string][]recentEvent
+	// 发送队列大小和慢客户端驱逐阈值
+	sendPolicy SendPolicy
+	metrics    SendMetrics // 发送队列指标（可选）
+	// 跨实例新邮件事件总线（可选，见 fanout.go）
+	bus      NewMailBus
+	busReady atomic.Bool // 已订阅总线，新邮件经总线发布
 }
 
 // BroadcastMessage 广播消息
 type BroadcastMessage struct {
 	MailboxID string
-	Message   *Message
+	Message   *wsproto.Message
 }
 
 // NewHub 创建WebSocket Hub
 //
 // 参数:
 //   - allowedOrigins: 允许的 Origin 列表，用于 WebSocket 连接验证
-//   - jwtSecret: JWT密钥，用于验证用户token
+//   - tokens: 用户令牌验证（通常为 JWT 管理器）
 //   - mailboxStore: 邮箱存储接口，用于验证邮箱权限
 //
 // 返回值:
 //   - *Hub: 创建的 Hub 实例
-func NewHub(allowedOrigins []string, jwtSecret string, mailboxStore MailboxStore) *Hub {
+func NewHub(allowedOrigins []string, tokens TokenValidator, mailboxStore MailboxStore) *Hub {
 	// 如果没有配置，默认允许所有
 	if len(allowedOrigins) == 0 {
 		allowedOrigins = []string{"*"}
@@ -149,8 +159,34 @@ func NewHub(allowedOrigins []string, jwtSecret string, mailboxStore MailboxStore
 		broadcast:      make(chan *BroadcastMessage, 256),
 		log:            zap.NewNop(), // 临时使用空日志
 		allowedOrigins: allowedOrigins,
-		jwtSecret:      jwtSecret,
+		tokens:         tokens,
 		mailboxStore:   mailboxStore,
+		recent:         make(map[string][]recentEvent),
+		sendPolicy:     SendPolicy{}.withDefaults(),
+	}
+}
+
+// SetActivityRecorder 设置邮箱访问记录
+func (h *Hub) SetActivityRecorder(recorder ActivityRecorder) {
+	h.activity = recorder
+}
+
+// ConnectionCount 当前在线连接数
+func (h *Hub) ConnectionCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// recordActivity 记录邮箱被访问（失败只记日志）
+func (h *Hub) recordActivity(ctx context.Context, mailboxIDs ...string) {
+	if h.activity == nil {
+		return
+	}
+	for _, mailboxID := range mailboxIDs {
+		if err := h.activity.Touch(ctx, mailboxID); err != nil {
+			h.log.Warn("failed to record mailbox activity", zap.String("mailboxID", mailboxID), zap.Error(err))
+		}
 	}
 }
 
@@ -158,6 +194,9 @@ func NewHub(allowedOrigins []string, jwtSecret string, mailboxStore MailboxStore
 func (h *Hub) Run(ctx context.Context) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
+	if h.bus != nil {
+		go h.receiveNewMail(ctx)
+	}
 
 	for {
 		select {
@@ -173,22 +212,7 @@ func (h *Hub) Run(ctx context.Context) {
 			h.log.Info("client registered", zap.String("id", client.ID))
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client.ID]; ok {
-				// 从所有邮箱订阅中移除
-				for mailboxID := range client.mailboxIDs {
-					if clients, exists := h.mailboxes[mailboxID]; exists {
-						delete(clients, client.ID)
-						if len(clients) == 0 {
-							delete(h.mailboxes, mailboxID)
-						}
-					}
-				}
-				delete(h.clients, client.ID)
-				close(client.send)
-				h.log.Info("client unregistered", zap.String("id", client.ID))
-			}
-			h.mu.Unlock()
+			h.removeClient(client)
 
 		case msg := <-h.broadcast:
 			h.broadcastToMailbox(msg.MailboxID, msg.Message)
@@ -196,81 +220,95 @@ func (h *Hub) Run(ctx context.Context) {
 		case <-ticker.C:
 			// 定期ping所有客户端
 			h.pingAllClients()
+			h.pruneRecentEvents(time.Now())
+			h.checkSessions(time.Now())
 		}
 	}
 }
 
-// NewMailData 新邮件通知数据
-type NewMailData struct {
-	MessageID string `json:"messageId"`
-	MailboxID string `json:"mailboxId"`
-	From      string `json:"from"`
-	To        string `json:"to"`
-	Subject   string `json:"subject"`
-	Preview   string `json:"preview,omitempty"`
-	HasHTML   bool   `json:"hasHtml"`
-	HasText   bool   `json:"hasText"`
-	CreatedAt string `json:"createdAt"`
-}
-
 // NotifyNewMail 通知新邮件
-func (h *Hub) NotifyNewMail(mailboxID string, message *domain.Message) {
-	// 构建前端期望的数据格式
-	preview := ""
-	if message.Text != "" && len(message.Text) > 100 {
-		preview = message.Text[:100]
-	} else if message.Text != "" {
-		preview = message.Text
+//
+// 已订阅跨实例总线时经总线发布，由各实例（包括本实例）收到后推送；发布失败时只推送给本实例的订阅者。
+func (h *Hub) NotifyNewMail(ctx context.Context, mailboxID string, message *domain.Message) {
+	summary := newMailSummary(mailboxID, message)
+	if h.bus != nil && h.busReady.Load() {
+		err := h.bus.PublishNewMail(ctx, mailboxID, summary)
+		if err == nil {
+			return
+		}
+		h.log.Warn("failed to publish new mail, delivering locally", zap.String("mailboxID", mailboxID), zap.Error(err))
 	}
 
-	newMailData := NewMailData{
-		MessageID: message.ID,
+	msg := h.newMailMessage(summary)
+	if msg == nil {
+		return
+	}
+	h.broadcast <- &BroadcastMessage{
 		MailboxID: mailboxID,
+		Message:   msg,
+	}
+}
+
+// newMailSummary 推送所需的邮件摘要，正文只保留预览
+func newMailSummary(mailboxID string, message *domain.Message) *domain.Message {
+	// 只有 HTML 的邮件入库时已生成纯文本；按字符截断，避免切断多字节字符
+	preview := strings.Join(strings.Fields(message.Text), " ")
+	if runes := []rune(preview); len(runes) > 100 {
+		preview = string(runes[:100])
+	}
+	return &domain.Message{
+		ID:        message.ID,
+		MailboxID: mailboxID,
+		Seq:       message.Seq,
 		From:      message.From,
 		To:        message.To,
 		Subject:   message.Subject,
-		Preview:   preview,
+		CreatedAt: message.CreatedAt,
 		HasHTML:   message.HTML != "",
 		HasText:   message.Text != "",
-		CreatedAt: message.CreatedAt.Format(time.RFC3339),
+		Text:      preview,
+	}
+}
+
+// newMailMessage 根据邮件摘要构建 new_mail 消息
+func (h *Hub) newMailMessage(summary *domain.Message) *wsproto.Message {
+	// 构建前端期望的数据格式
+	newMailData := wsproto.NewMailData{
+		MessageID: summary.ID,
+		MailboxID: summary.MailboxID,
+		Seq:       summary.Seq,
+		From:      summary.From,
+		To:        summary.To,
+		Subject:   summary.Subject,
+		Preview:   summary.Text,
+		HasHTML:   summary.HasHTML,
+		HasText:   summary.HasText,
+		CreatedAt: summary.CreatedAt.Format(time.RFC3339),
 	}
 
 	data, err := json.Marshal(newMailData)
 	if err != nil {
 		h.log.Error("failed to marshal new mail data", zap.Error(err))
-		return
-	}
-
-	msg := &Message{
-		Type:      MessageTypeNewMail,
-		MailboxID: mailboxID,
-		Data:      data,
-		Timestamp: time.Now(),
+		return nil
 	}
 
 	h.log.Info("broadcasting new mail notification",
-		zap.String("mailboxID", mailboxID),
-		zap.String("from", message.From),
-		zap.String("subject", message.Subject))
+		zap.String("mailboxID", summary.MailboxID),
+		zap.String("from", summary.From),
+		zap.String("subject", summary.Subject))
 
-	h.broadcast <- &BroadcastMessage{
-		MailboxID: mailboxID,
-		Message:   msg,
+	return &wsproto.Message{
+		Type:      wsproto.MessageTypeNewMail,
+		MailboxID: summary.MailboxID,
+		Data:      data,
+		Timestamp: time.Now(),
 	}
 }
 
-// MailboxUpdateData 邮箱更新通知数据
-type MailboxUpdateData struct {
-	MailboxID    string `json:"mailboxId"`
-	UnreadCount  int    `json:"unreadCount"`
-	TotalCount   int    `json:"totalCount"`
-	LastActivity string `json:"lastActivity"`
-}
-
 // NotifyMailboxUpdate 通知邮箱更新
 func (h *Hub) NotifyMailboxUpdate(mailbox *domain.Mailbox) {
 	// 构建前端期望的数据格式
-	updateData := MailboxUpdateData{
+	updateData := wsproto.MailboxUpdateData{
 		MailboxID:    mailbox.ID,
 		UnreadCount:  mailbox.Unread,
 		TotalCount:   mailbox.TotalCount,
@@ -283,8 +321,8 @@ func (h *Hub) NotifyMailboxUpdate(mailbox *domain.Mailbox) {
 		return
 	}
 
-	msg := &Message{
-		Type:      MessageTypeMailboxUpdate,
+	msg := &wsproto.Message{
+		Type:      wsproto.MessageTypeMailboxUpdate,
 		MailboxID: mailbox.ID,
 		Data:      data,
 		Timestamp: time.Now(),
@@ -301,36 +339,104 @@ func (h *Hub) NotifyMailboxUpdate(mailbox *domain.Mailbox) {
 	}
 }
 
-// broadcastToMailbox 向订阅特定邮箱的客户端广播消息
-func (h *Hub) broadcastToMailbox(mailboxID string, msg *Message) {
-	h.mu.RLock()
-	clients := h.mailboxes[mailboxID]
-	h.mu.RUnlock()
+// NotifyMailboxDeleted 通知邮箱已删除
+//
+// 向订阅者推送 mailbox_deleted 事件，然后撤销所有客户端对该邮箱的订阅和访问权限，
+// 客户端无需重连即可感知邮箱失效。
+func (h *Hub) NotifyMailboxDeleted(mailboxID string) {
+	data, err := json.Marshal(wsproto.MailboxDeletedData{
+		MailboxID: mailboxID,
+		DeletedAt: time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		h.log.Error("failed to marshal mailbox deleted data", zap.Error(err))
+		return
+	}
 
-	if len(clients) == 0 {
+	payload, err := json.Marshal(&wsproto.Message{
+		Type:      wsproto.MessageTypeMailboxDeleted,
+		MailboxID: mailboxID,
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		h.log.Error("failed to marshal message", zap.Error(err))
 		return
 	}
 
-	data, err := json.Marshal(msg)
+	// 持有 Hub 锁发送，避免与注销时关闭 send 通道并发
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, client := range h.mailboxes[mailboxID] {
+		h.enqueue(client, payload)
+	}
+	delete(h.mailboxes, mailboxID)
+	delete(h.recent, mailboxID)
+
+	for _, client := range h.clients {
+		client.revokeMailbox(mailboxID)
+	}
+
+	h.log.Info("mailbox deleted, subscriptions revoked", zap.String("mailboxID", mailboxID))
+}
+
+// NotifyUser 向用户的所有 JWT 连接推送事件（与邮箱订阅无关）
+func (h *Hub) NotifyUser(userID, event string, data interface{}) {
+	if userID == "" {
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		h.log.Error("failed to marshal user event data", zap.Error(err))
+		return
+	}
+	payload, err := json.Marshal(&wsproto.Message{
+		Type:      wsproto.MessageType(event),
+		Data:      raw,
+		Timestamp: time.Now(),
+	})
 	if err != nil {
 		h.log.Error("failed to marshal message", zap.Error(err))
 		return
 	}
 
-	for _, client := range clients {
-		select {
-		case client.send <- data:
-		default:
-			// 客户端阻塞，跳过
-			h.log.Warn("client channel blocked, skipping", zap.String("clientID", client.ID))
+	// 持有 Hub 锁发送，避免与注销时关闭 send 通道并发
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, client := range h.clients {
+		if client.IsMailbox || client.UserID != userID {
+			continue
 		}
+		h.enqueue(client, payload)
+	}
+}
+
+// broadcastToMailbox 向订阅特定邮箱的客户端广播消息
+func (h *Hub) broadcastToMailbox(mailboxID string, msg *wsproto.Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		h.log.Error("failed to marshal message", zap.Error(err))
+		return
+	}
+
+	// 记录事件与发送在同一临界区内，与 subscribeMailbox 互斥，保证每个订阅者只收到一次；
+	// 持有 Hub 锁发送也避免与注销时关闭 send 通道并发（入队不阻塞，读取过慢的客户端按背压策略处理）
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if msg.Type == wsproto.MessageTypeNewMail && h.replayWindow > 0 {
+		h.rememberEvent(mailboxID, msg)
+	}
+	for _, client := range h.mailboxes[mailboxID] {
+		h.enqueue(client, data)
 	}
 }
 
 // pingAllClients 向所有客户端发送ping
 func (h *Hub) pingAllClients() {
-	msg := &Message{
-		Type:      MessageTypePing,
+	msg := &wsproto.Message{
+		Type:      wsproto.MessageTypePing,
 		Timestamp: time.Now(),
 	}
 
@@ -339,16 +445,34 @@ func (h *Hub) pingAllClients() {
 		return
 	}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	// 心跳同样计入背压：长时间不读取的客户端在这里被驱逐，即使其订阅的邮箱没有新事件
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
 	for _, client := range h.clients {
-		select {
-		case client.send <- data:
-		default:
-			// 跳过阻塞的客户端
+		h.enqueue(client, data)
+	}
+}
+
+// removeClient 移除客户端及其全部订阅并关闭发送队列，已移除时不做任何事
+func (h *Hub) removeClient(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[client.ID]; !ok {
+		return
+	}
+	// 从所有邮箱订阅中移除
+	for mailboxID := range client.mailboxIDs {
+		if clients, exists := h.mailboxes[mailboxID]; exists {
+			delete(clients, client.ID)
+			if len(clients) == 0 {
+				delete(h.mailboxes, mailboxID)
+			}
 		}
 	}
+	delete(h.clients, client.ID)
+	close(client.send)
+	h.log.Info("client unregistered", zap.String("id", client.ID))
 }
 
 // closeAllClients 关闭所有客户端连接
@@ -377,14 +501,24 @@ func (h *Hub) authenticateClient(c *gin.Context) (*Client, error) {
 		}
 	}
 
+	// 未携带令牌：只允许订阅公开收件箱（只读）
 	if token == "" {
-		return nil, errors.New("missing authentication token")
+		if mailboxID := c.Query("mailboxId"); mailboxID == "" || !h.isPublicMailbox(c.Request.Context(), mailboxID) {
+			return nil, errors.New("missing authentication token")
+		}
+		return &Client{
+			ID:         generateClientID(),
+			mailboxIDs: make(map[string]bool),
+			publicIDs:  make(map[string]bool),
+			log:        h.log,
+		}, nil
 	}
 
 	// 尝试JWT认证
-	if userID, email, err := h.validateJWT(token); err == nil {
+	if claims, err := h.jwtClaims(c.Request.Context(), token); err == nil {
 		// JWT认证成功，获取用户的所有邮箱
-		mailboxes := h.mailboxStore.ListMailboxesByUserID(userID)
+		userID, email := claims.UserID, claims.Email
+		mailboxes := h.userMailboxes(c.Request.Context(), userID)
 
 		permissions := make([]string, len(mailboxes))
 		for i, mb := range mailboxes {
@@ -398,7 +532,9 @@ func (h *Hub) authenticateClient(c *gin.Context) (*Client, error) {
 			IsMailbox:   false,
 			Permissions: permissions,
 			mailboxIDs:  make(map[string]bool),
+			publicIDs:   make(map[string]bool),
 			log:         h.log,
+			expiresAt:   tokenExpiry(claims),
 		}
 
 		h.log.Info("JWT authentication successful",
@@ -410,7 +546,7 @@ func (h *Hub) authenticateClient(c *gin.Context) (*Client, error) {
 	}
 
     // 尝试Mailbox Token认证（需要提供 mailboxId 并且 token 与之匹配）
-    if mailboxID, err := h.validateMailboxToken(token, c.Query("mailboxId")); err == nil {
+    if mailboxID, err := h.validateMailboxToken(c.Request.Context(), token, c.Query("mailboxId")); err == nil {
         client := &Client{
             ID:          generateClientID(),
             MailboxID:   mailboxID,
@@ -418,6 +554,7 @@ func (h *Hub) authenticateClient(c *gin.Context) (*Client, error) {
             IsMailbox:   true,
             Permissions: []string{mailboxID},
             mailboxIDs:  make(map[string]bool),
+            publicIDs:   make(map[string]bool),
             log:         h.log,
         }
 
@@ -430,34 +567,149 @@ func (h *Hub) authenticateClient(c *gin.Context) (*Client, error) {
 	return nil, errors.New("invalid authentication token")
 }
 
-// validateJWT 验证JWT token
-func (h *Hub) validateJWT(tokenString string) (userID, email string, err error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// userMailboxes 获取用户可访问的邮箱（个人邮箱及所在组织的邮箱）
+func (h *Hub) userMailboxes(ctx context.Context, userID string) []domain.Mailbox {
+	mailboxes := h.mailboxStore.ListMailboxesByUserID(ctx, userID)
+	orgStore, ok := h.mailboxStore.(OrgMailboxStore)
+	if !ok {
+		return withoutSuspended(mailboxes)
+	}
+	members, err := orgStore.ListOrgMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return withoutSuspended(mailboxes)
+	}
+	// 组织邮箱按当前成员关系授权，不因创建者身份保留
+	personal := mailboxes[:0]
+	for _, mb := range mailboxes {
+		if !domain.InOrg(mb.OrgID) {
+			personal = append(personal, mb)
+		}
+	}
+	mailboxes = personal
+	for _, member := range members {
+		mailboxes = append(mailboxes, orgStore.ListMailboxesByOrgID(ctx, member.OrgID)...)
+	}
+	return withoutSuspended(mailboxes)
+}
+
+// withoutSuspended 去掉被停用的邮箱（停用后所有者也不能订阅）
+func withoutSuspended(mailboxes []domain.Mailbox) []domain.Mailbox {
+	active := mailboxes[:0]
+	for _, mb := range mailboxes {
+		if !mb.Suspended {
+			active = append(active, mb)
+		}
+	}
+	return active
+}
+
+// isPublicMailbox 检查邮箱是否为公开收件箱
+func (h *Hub) isPublicMailbox(ctx context.Context, mailboxID string) bool {
+	if h.mailboxStore == nil {
+		return false
+	}
+	mailbox, err := h.mailboxStore.GetMailbox(ctx, mailboxID)
+	return err == nil && mailbox != nil && mailbox.IsPublic && !mailbox.Suspended
+}
+
+// RevokePublicAccess 撤销通过公开权限建立的订阅（邮箱被取消公开时调用）
+//
+// 只影响凭公开权限订阅的客户端：向其推送 public_access_revoked 后移除订阅；
+// 凭令牌或所有者身份订阅的客户端不受影响。
+func (h *Hub) RevokePublicAccess(mailboxID string) {
+	payload, err := json.Marshal(&wsproto.Message{
+		Type:      wsproto.MessageTypePublicRevoked,
+		MailboxID: mailboxID,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		h.log.Error("failed to marshal message", zap.String("mailboxID", mailboxID), zap.Error(err))
+		return
+	}
+
+	// 持有 Hub 锁发送，避免与注销时关闭 send 通道并发
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	revoked := 0
+	for clientID, client := range h.mailboxes[mailboxID] {
+		if !client.dropPublic(mailboxID) {
+			continue
 		}
-		return []byte(h.jwtSecret), nil
+		delete(h.mailboxes[mailboxID], clientID)
+		revoked++
+		h.enqueue(client, payload)
+	}
+	if len(h.mailboxes[mailboxID]) == 0 {
+		delete(h.mailboxes, mailboxID)
+	}
+
+	h.log.Info("public access revoked", zap.String("mailboxID", mailboxID), zap.Int("clients", revoked))
+}
+
+// SuspendMailbox 撤销对被停用邮箱的全部访问（管理员因滥用停用邮箱时调用）
+//
+// 凭该邮箱令牌建立的连接以 CloseMailboxSuspended 断开；其他订阅者（所有者、组织成员、公开订阅）
+// 收到 mailbox_suspended 后移除订阅，连接保持。
+func (h *Hub) SuspendMailbox(mailboxID string) {
+	payload, err := json.Marshal(&wsproto.Message{
+		Type:      wsproto.MessageTypeMailboxSuspended,
+		MailboxID: mailboxID,
+		Timestamp: time.Now(),
 	})
+	if err != nil {
+		h.log.Error("failed to marshal message", zap.String("mailboxID", mailboxID), zap.Error(err))
+		return
+	}
+
+	// 持有 Hub 锁发送，避免与注销时关闭 send 通道并发
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	closed := 0
+	for _, client := range h.clients {
+		if client.IsMailbox && client.MailboxID == mailboxID {
+			h.closeSession(client, wsproto.CloseMailboxSuspended, "mailbox suspended")
+			closed++
+		}
+	}
+	for _, client := range h.mailboxes[mailboxID] {
+		h.enqueue(client, payload)
+	}
+	delete(h.mailboxes, mailboxID)
+	delete(h.recent, mailboxID)
+	for _, client := range h.clients {
+		client.revokeMailbox(mailboxID)
+	}
 
+	h.log.Info("mailbox suspended, access revoked", zap.String("mailboxID", mailboxID), zap.Int("closed", closed))
+}
+
+// validateJWT 验证JWT token
+func (h *Hub) validateJWT(ctx context.Context, tokenString string) (userID, email string, err error) {
+	claims, err := h.jwtClaims(ctx, tokenString)
 	if err != nil {
 		return "", "", err
 	}
+	return claims.UserID, claims.Email, nil
+}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims.UserID, claims.Email, nil
+// jwtClaims 验证JWT token并返回声明
+func (h *Hub) jwtClaims(ctx context.Context, tokenString string) (*jwtpkg.Claims, error) {
+	if h.tokens == nil {
+		return nil, errors.New("jwt authentication not configured")
 	}
-
-	return "", "", errors.New("invalid token claims")
+	return h.tokens.Authenticate(ctx, tokenString)
 }
 
 // validateMailboxToken 验证邮箱token
-func (h *Hub) validateMailboxToken(token, mailboxID string) (string, error) {
+func (h *Hub) validateMailboxToken(ctx context.Context, token, mailboxID string) (string, error) {
     if mailboxID == "" {
         return "", errors.New("invalid mailbox token")
     }
 
-    mailbox, err := h.mailboxStore.GetMailbox(mailboxID)
-    if err != nil || mailbox == nil || mailbox.Token == "" {
+    mailbox, err := h.mailboxStore.GetMailbox(ctx, mailboxID)
+    if err != nil || mailbox == nil || mailbox.Token == "" || mailbox.Suspended {
         return "", errors.New("invalid mailbox token")
     }
 
@@ -497,9 +749,13 @@ func HandleWebSocket(hub *Hub) gin.HandlerFunc {
 		// 设置连接和Hub
 		client.conn = conn
 		client.hub = hub
-		client.send = make(chan []byte, 256)
+		hub.mu.RLock()
+		client.send = make(chan []byte, hub.sendPolicy.BufferSize)
+		hub.mu.RUnlock()
+		client.overflow = make(chan struct{})
 
-		// 注册客户端
+		// 先发送 hello 告知协议版本，再注册客户端
+		client.sendHello()
 		hub.register <- client
 
 		// 启动读写协程
@@ -522,7 +778,7 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		var msg Message
+		var msg wsproto.Message
 		err := c.conn.ReadJSON(&msg)
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
@@ -532,7 +788,7 @@ func (c *Client) readPump() {
 		}
 
 		// 处理消息
-		c.handleMessage(&msg)
+		c.handleMessage(context.Background(), &msg)
 	}
 }
 
@@ -555,6 +811,13 @@ func (c *Client) writePump() {
 
 			c.conn.WriteMessage(websocket.TextMessage, message)
 
+		case <-c.overflow:
+			// 被驱逐：写出队列中剩下的 overflow 错误，再以 CloseSlowConsumer 断开
+			c.flushQueued()
+			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(wsproto.CloseSlowConsumer, "send queue overflow"))
+			return
+
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -564,74 +827,166 @@ func (c *Client) writePump() {
 	}
 }
 
+// flushQueued 写出发送队列中已有的消息（不等待新消息）
+func (c *Client) flushQueued() {
+	for {
+		select {
+		case message, ok := <-c.send:
+			if !ok {
+				return
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
 // handleMessage 处理接收到的消息
-func (c *Client) handleMessage(msg *Message) {
+func (c *Client) handleMessage(ctx context.Context, msg *wsproto.Message) {
 	switch msg.Type {
-	case MessageTypeSubscribe:
-		c.subscribeMailbox(msg.MailboxID)
-	case MessageTypeUnsubscribe:
+	case wsproto.MessageTypeSubscribe:
+		var req wsproto.SubscribeData
+		if len(msg.Data) > 0 {
+			if err := json.Unmarshal(msg.Data, &req); err != nil {
+				c.sendError(wsproto.ErrCodeInvalidRequest, "invalid subscribe data")
+				return
+			}
+		}
+		c.subscribeMailbox(ctx, msg.MailboxID, req)
+	case wsproto.MessageTypeUnsubscribe:
 		c.unsubscribeMailbox(msg.MailboxID)
-	case MessageTypePong:
+	case wsproto.MessageTypeReauth:
+		c.reauthenticate(ctx, msg)
+	case wsproto.MessageTypePong:
 		// 客户端响应pong，更新活动时间
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		c.hub.recordActivity(ctx, c.subscribedMailboxIDs()...)
 	default:
+		// 忽略不认识的消息类型（较新的客户端可能发送本服务端尚不支持的消息）
 		c.log.Warn("unknown message type", zap.String("type", string(msg.Type)))
 	}
 }
 
 // subscribeMailbox 订阅邮箱
-func (c *Client) subscribeMailbox(mailboxID string) {
+//
+// 按请求的协议版本协商本连接使用的版本，在 subscribed 中回显；req.SinceSeq 为恢复游标，只补发之后的事件。
+func (c *Client) subscribeMailbox(ctx context.Context, mailboxID string, req wsproto.SubscribeData) {
 	if mailboxID == "" {
-		c.sendError("mailbox ID is required")
+		c.sendError(wsproto.ErrCodeInvalidRequest, "mailbox ID is required")
+		return
+	}
+	version, ok := wsproto.Negotiate(req.ProtocolVersion)
+	if !ok {
+		c.sendError(wsproto.ErrCodeUnsupportedVersion, fmt.Sprintf("protocol version %d is not supported (minimum %d)", req.ProtocolVersion, wsproto.MinVersion))
 		return
 	}
 
 	// 验证权限
 	hasPermission := false
+	c.mu.RLock()
 	for _, permMailboxID := range c.Permissions {
 		if permMailboxID == mailboxID {
 			hasPermission = true
 			break
 		}
 	}
+	c.mu.RUnlock()
+
+	// 无令牌权限时，公开收件箱可只读订阅
+	public := false
+	if !hasPermission && c.hub.isPublicMailbox(context.Background(), mailboxID) {
+		hasPermission, public = true, true
+	}
 
 	if !hasPermission {
 		c.log.Warn("subscription denied: no permission",
 			zap.String("clientID", c.ID),
 			zap.String("mailboxID", mailboxID),
 			zap.Bool("isMailbox", c.IsMailbox))
-		c.sendError(fmt.Sprintf("no permission to access mailbox: %s", mailboxID))
+		c.sendError(wsproto.ErrCodeForbidden, fmt.Sprintf("no permission to access mailbox: %s", mailboxID))
 		return
 	}
 
+	c.hub.mu.Lock()
+	// 在 Hub 锁内复查，避免与 RevokePublicAccess 交错后留下已撤销的公开订阅
+	if public && !c.hub.isPublicMailbox(context.Background(), mailboxID) {
+		c.hub.mu.Unlock()
+		c.sendError(wsproto.ErrCodeForbidden, fmt.Sprintf("no permission to access mailbox: %s", mailboxID))
+		return
+	}
 	c.mu.Lock()
+	resubscribe := c.mailboxIDs[mailboxID]
 	c.mailboxIDs[mailboxID] = true
+	if public {
+		c.publicIDs[mailboxID] = true
+	}
 	c.mu.Unlock()
-
-	c.hub.mu.Lock()
 	if c.hub.mailboxes[mailboxID] == nil {
 		c.hub.mailboxes[mailboxID] = make(map[string]*Client)
 	}
 	c.hub.mailboxes[mailboxID][c.ID] = c
+
+	// 确认和补发事件在 Hub 锁内入队，之后广播的实时事件排在它们之后
+	c.sendMessage(&wsproto.Message{
+		Type:            wsproto.MessageTypeSubscribed,
+		MailboxID:       mailboxID,
+		Timestamp:       time.Now(),
+		ProtocolVersion: version,
+	})
+	if !resubscribe {
+		for _, msg := range c.hub.replayEvents(mailboxID, time.Now(), req.SinceSeq) {
+			c.sendMessage(msg)
+		}
+	}
 	c.hub.mu.Unlock()
 
 	c.log.Info("subscribed to mailbox",
 		zap.String("clientID", c.ID),
 		zap.String("mailboxID", mailboxID),
-		zap.String("userID", c.UserID))
+		zap.String("userID", c.UserID),
+		zap.Bool("public", public))
+	if !public {
+		c.hub.recordActivity(ctx, mailboxID) // 匿名查看不算所有者访问
+	}
+}
 
-	// 发送订阅成功确认
-	c.sendMessage(&Message{
-		Type:      "subscribed",
-		MailboxID: mailboxID,
-		Timestamp: time.Now(),
+// sendHello 发送连接建立后的第一条消息，告知服务端支持的协议版本
+func (c *Client) sendHello() {
+	data, err := json.Marshal(wsproto.HelloData{ProtocolVersion: wsproto.Version, MinProtocolVersion: wsproto.MinVersion})
+	if err != nil {
+		c.log.Error("failed to marshal hello data", zap.Error(err))
+		return
+	}
+	c.sendMessage(&wsproto.Message{
+		Type:            wsproto.MessageTypeHello,
+		Data:            data,
+		Timestamp:       time.Now(),
+		ProtocolVersion: wsproto.Version,
 	})
 }
 
+// subscribedMailboxIDs 返回当前凭令牌订阅的邮箱ID（公开订阅不算所有者访问）
+func (c *Client) subscribedMailboxIDs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ids := make([]string, 0, len(c.mailboxIDs))
+	for id := range c.mailboxIDs {
+		if !c.publicIDs[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 // sendError 发送错误消息给客户端
-func (c *Client) sendError(errMsg string) {
-	msg := &Message{
-		Type:      MessageTypeError,
+func (c *Client) sendError(code wsproto.ErrorCode, errMsg string) {
+	msg := &wsproto.Message{
+		Type:      wsproto.MessageTypeError,
+		Code:      code,
 		Error:     errMsg,
 		Timestamp: time.Now(),
 	}
@@ -639,16 +994,15 @@ func (c *Client) sendError(errMsg string) {
 }
 
 // sendMessage 发送消息给客户端
-func (c *Client) sendMessage(msg *Message) {
+func (c *Client) sendMessage(msg *wsproto.Message) {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		c.log.Error("failed to marshal message", zap.Error(err))
 		return
 	}
 
-	select {
-	case c.send <- data:
-	default:
+	// 回复不经过 Hub 锁，只计入丢弃；是否驱逐在下一次广播或心跳时判断
+	if pending, _ := c.push(data, time.Now()); pending > 0 {
 		c.log.Warn("client channel blocked", zap.String("clientID", c.ID))
 	}
 }
@@ -657,6 +1011,7 @@ func (c *Client) sendMessage(msg *Message) {
 func (c *Client) unsubscribeMailbox(mailboxID string) {
 	c.mu.Lock()
 	delete(c.mailboxIDs, mailboxID)
+	delete(c.publicIDs, mailboxID)
 	c.mu.Unlock()
 
 	c.hub.mu.Lock()
@@ -673,6 +1028,35 @@ func (c *Client) unsubscribeMailbox(mailboxID string) {
 		zap.String("mailboxID", mailboxID))
 }
 
+// revokeMailbox 移除客户端对邮箱的订阅和访问权限
+func (c *Client) revokeMailbox(mailboxID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.mailboxIDs, mailboxID)
+	delete(c.publicIDs, mailboxID)
+	permissions := c.Permissions[:0]
+	for _, permMailboxID := range c.Permissions {
+		if permMailboxID != mailboxID {
+			permissions = append(permissions, permMailboxID)
+		}
+	}
+	c.Permissions = permissions
+}
+
+// dropPublic 移除通过公开权限建立的订阅，返回是否移除
+func (c *Client) dropPublic(mailboxID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.publicIDs[mailboxID] {
+		return false
+	}
+	delete(c.publicIDs, mailboxID)
+	delete(c.mailboxIDs, mailboxID)
+	return true
+}
+
 // generateClientID 生成客户端ID
 func generateClientID() string {
 	return time.Now().Format("20060102150405") + "-" + generateRandomString(8)
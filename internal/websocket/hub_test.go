@@ -0,0 +1,514 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+	"tempmail/backend/pkg/wsproto"
+)
+
+func TestHub_ValidateJWTAcrossRotation(t *testing.T) {
+	manager := jwtpkg.NewManager("old-secret-0123456789abcdefghijklmnop", "tempmail", 15*time.Minute, time.Hour)
+	hub := NewHub(nil, manager, nil)
+
+	before, err := manager.GenerateTokenPair(t.Context(), "user-1", "u@example.com", "free")
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Rotate(jwtpkg.Key{Secret: "new-secret-0123456789abcdefghijklmnop"}))
+
+	t.Run("轮换前签发的令牌仍可连接", func(t *testing.T) {
+		userID, email, err := hub.validateJWT(t.Context(), before.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+		assert.Equal(t, "u@example.com", email)
+	})
+
+	t.Run("轮换后签发的令牌可连接", func(t *testing.T) {
+		after, err := manager.GenerateTokenPair(t.Context(), "user-1", "u@example.com", "free")
+		require.NoError(t, err)
+		userID, _, err := hub.validateJWT(t.Context(), after.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+	})
+
+	t.Run("其他密钥签发的令牌被拒绝", func(t *testing.T) {
+		other := jwtpkg.NewManager("another-secret-0123456789abcdefghijk", "tempmail", 15*time.Minute, time.Hour)
+		pair, err := other.GenerateTokenPair(t.Context(), "user-1", "u@example.com", "free")
+		require.NoError(t, err)
+		_, _, err = hub.validateJWT(t.Context(), pair.AccessToken)
+		assert.Error(t, err)
+	})
+}
+
+// publicMailboxStore 内存邮箱表（测试公开订阅）
+type publicMailboxStore map[string]*domain.Mailbox
+
+func (s publicMailboxStore) GetMailbox(ctx context.Context, id string) (*domain.Mailbox, error) {
+	mailbox, ok := s[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return mailbox, nil
+}
+
+func (s publicMailboxStore) ListMailboxesByUserID(context.Context, string) []domain.Mailbox {
+	return nil
+}
+
+func newTestClient(hub *Hub, permissions ...string) *Client {
+	return &Client{
+		ID:          generateClientID() + "-" + strings.Join(permissions, ","),
+		hub:         hub,
+		send:        make(chan []byte, 16),
+		mailboxIDs:  make(map[string]bool),
+		publicIDs:   make(map[string]bool),
+		Permissions: permissions,
+		log:         zap.NewNop(),
+	}
+}
+
+// lastMessage 读取发给客户端的最后一条消息
+func lastMessage(t *testing.T, c *Client) wsproto.Message {
+	t.Helper()
+	var msg wsproto.Message
+	for {
+		select {
+		case data := <-c.send:
+			require.NoError(t, json.Unmarshal(data, &msg))
+		default:
+			return msg
+		}
+	}
+}
+
+func TestHub_PublicSubscriptions(t *testing.T) {
+	store := publicMailboxStore{
+		"pub":  {ID: "pub", IsPublic: true},
+		"priv": {ID: "priv"},
+	}
+	hub := NewHub(nil, nil, store)
+
+	anonymous := newTestClient(hub)
+	owner := newTestClient(hub, "pub")
+
+	t.Run("匿名客户端只能订阅公开收件箱", func(t *testing.T) {
+		anonymous.subscribeMailbox(t.Context(), "priv", wsproto.SubscribeData{})
+		assert.Equal(t, wsproto.MessageTypeError, lastMessage(t, anonymous).Type)
+
+		anonymous.subscribeMailbox(t.Context(), "pub", wsproto.SubscribeData{})
+		assert.Equal(t, wsproto.MessageTypeSubscribed, lastMessage(t, anonymous).Type)
+		owner.subscribeMailbox(t.Context(), "pub", wsproto.SubscribeData{})
+		assert.Equal(t, wsproto.MessageTypeSubscribed, lastMessage(t, owner).Type)
+		assert.Len(t, hub.mailboxes["pub"], 2)
+		assert.Empty(t, anonymous.subscribedMailboxIDs(), "公开订阅不算所有者访问")
+	})
+
+	t.Run("取消公开只撤销匿名订阅", func(t *testing.T) {
+		store["pub"].IsPublic = false
+		hub.RevokePublicAccess("pub")
+
+		msg := lastMessage(t, anonymous)
+		assert.Equal(t, wsproto.MessageTypePublicRevoked, msg.Type)
+		assert.Equal(t, "pub", msg.MailboxID)
+		assert.Empty(t, lastMessage(t, owner).Type, "所有者不收到撤销通知")
+
+		require.Len(t, hub.mailboxes["pub"], 1)
+		assert.Contains(t, hub.mailboxes["pub"], owner.ID)
+
+		anonymous.subscribeMailbox(t.Context(), "pub", wsproto.SubscribeData{})
+		assert.Equal(t, wsproto.MessageTypeError, lastMessage(t, anonymous).Type)
+	})
+}
+
+// drainMessages 读取发给客户端的全部消息
+func drainMessages(t *testing.T, c *Client) []wsproto.Message {
+	t.Helper()
+	var messages []wsproto.Message
+	for {
+		select {
+		case data := <-c.send:
+			var msg wsproto.Message
+			require.NoError(t, json.Unmarshal(data, &msg))
+			messages = append(messages, msg)
+		default:
+			return messages
+		}
+	}
+}
+
+// ingest 模拟收信：NotifyNewMail 入队后由 Run 循环广播（此处同步处理）
+func ingest(hub *Hub, mailboxID, messageID string) {
+	hub.NotifyNewMail(context.Background(), mailboxID, &domain.Message{ID: messageID, MailboxID: mailboxID, Subject: messageID})
+	b := <-hub.broadcast
+	hub.broadcastToMailbox(b.MailboxID, b.Message)
+}
+
+func newMailIDs(t *testing.T, messages []wsproto.Message) (ids []string, replayed []bool) {
+	t.Helper()
+	for _, msg := range messages {
+		if msg.Type != wsproto.MessageTypeNewMail {
+			continue
+		}
+		var data wsproto.NewMailData
+		require.NoError(t, json.Unmarshal(msg.Data, &data))
+		ids = append(ids, data.MessageID)
+		replayed = append(replayed, msg.Replayed)
+	}
+	return ids, replayed
+}
+
+func TestHub_ReplayOnSubscribe(t *testing.T) {
+	t.Run("先收信后订阅仍能收到事件", func(t *testing.T) {
+		hub := NewHub(nil, nil, nil)
+		hub.SetReplayWindow(5 * time.Minute)
+		ingest(hub, "mb-1", "first")
+
+		client := newTestClient(hub, "mb-1")
+		client.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		ingest(hub, "mb-1", "second")
+
+		messages := drainMessages(t, client)
+		require.NotEmpty(t, messages)
+		assert.Equal(t, wsproto.MessageTypeSubscribed, messages[0].Type)
+		ids, replayed := newMailIDs(t, messages)
+		assert.Equal(t, []string{"first", "second"}, ids, "补发事件在实时事件之前，且不重复")
+		assert.Equal(t, []bool{true, false}, replayed)
+	})
+
+	t.Run("重复订阅不再补发", func(t *testing.T) {
+		hub := NewHub(nil, nil, nil)
+		hub.SetReplayWindow(5 * time.Minute)
+		ingest(hub, "mb-1", "first")
+
+		client := newTestClient(hub, "mb-1")
+		client.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		drainMessages(t, client)
+		client.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		ids, _ := newMailIDs(t, drainMessages(t, client))
+		assert.Empty(t, ids)
+	})
+
+	t.Run("只补发窗口内的事件", func(t *testing.T) {
+		hub := NewHub(nil, nil, nil)
+		hub.SetReplayWindow(5 * time.Minute)
+		hub.broadcastToMailbox("mb-1", &wsproto.Message{Type: wsproto.MessageTypeNewMail, MailboxID: "mb-1", Timestamp: time.Now().Add(-10 * time.Minute)})
+		ingest(hub, "mb-1", "recent")
+
+		client := newTestClient(hub, "mb-1")
+		client.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		ids, _ := newMailIDs(t, drainMessages(t, client))
+		assert.Equal(t, []string{"recent"}, ids)
+
+		hub.pruneRecentEvents(time.Now().Add(10 * time.Minute))
+		assert.Empty(t, hub.recent, "过期事件被清理")
+	})
+
+	t.Run("未开启补发时不保留事件", func(t *testing.T) {
+		hub := NewHub(nil, nil, nil)
+		ingest(hub, "mb-1", "first")
+
+		client := newTestClient(hub, "mb-1")
+		client.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		ids, _ := newMailIDs(t, drainMessages(t, client))
+		assert.Empty(t, ids)
+		assert.Empty(t, hub.recent)
+	})
+}
+
+// sessionFixture JWT 连接的会话测试环境（邮箱和用户保存在内存存储中）
+type sessionFixture struct {
+	store   *memory.Store
+	manager *jwtpkg.Manager
+	hub     *Hub
+	client  *Client
+}
+
+func newSessionFixture(t *testing.T) *sessionFixture {
+	t.Helper()
+	store := memory.NewStore(24 * time.Hour)
+	manager := jwtpkg.NewManager("session-secret-0123456789abcdefghijk", "tempmail", 15*time.Minute, time.Hour)
+	hub := NewHub(nil, manager, store)
+
+	now := time.Now()
+	expires := now.Add(time.Hour)
+	for _, id := range []string{"user-1", "user-2"} {
+		require.NoError(t, store.CreateUser(t.Context(), &domain.User{ID: id, Email: id + "@example.com", Username: id, IsActive: true, Role: domain.RoleUser}))
+	}
+	owner := "user-1"
+	require.NoError(t, store.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-1", Address: "a@temp.mail", Token: "mb-token", UserID: &owner, CreatedAt: now, ExpiresAt: &expires}))
+
+	pair, err := manager.GenerateTokenPair(t.Context(), "user-1", "user-1@example.com", "free")
+	require.NoError(t, err)
+	claims, err := manager.ValidateToken(pair.AccessToken)
+	require.NoError(t, err)
+
+	client := newTestClient(hub, "mb-1")
+	client.UserID = "user-1"
+	client.Token = pair.AccessToken
+	client.expiresAt = tokenExpiry(claims)
+	hub.clients[client.ID] = client
+	client.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+	drainMessages(t, client)
+
+	return &sessionFixture{store: store, manager: manager, hub: hub, client: client}
+}
+
+// reauth 以指定用户签发新令牌并发送 reauth 消息
+func (f *sessionFixture) reauth(t *testing.T, token string) {
+	t.Helper()
+	data, err := json.Marshal(wsproto.ReauthData{Token: token})
+	require.NoError(t, err)
+	f.client.handleMessage(t.Context(), &wsproto.Message{Type: wsproto.MessageTypeReauth, Data: data})
+}
+
+func (f *sessionFixture) token(t *testing.T, userID string) string {
+	t.Helper()
+	pair, err := f.manager.GenerateTokenPair(t.Context(), userID, userID+"@example.com", "free")
+	require.NoError(t, err)
+	return pair.AccessToken
+}
+
+func TestHub_SessionExpiry(t *testing.T) {
+	t.Run("过期前提醒一次，宽限期后断开", func(t *testing.T) {
+		f := newSessionFixture(t)
+		expiresAt := f.client.expiresAt
+		require.False(t, expiresAt.IsZero())
+
+		f.hub.checkSessions(expiresAt.Add(-AuthExpiringLead - time.Minute))
+		assert.Empty(t, drainMessages(t, f.client))
+
+		f.hub.checkSessions(expiresAt.Add(-time.Minute))
+		messages := drainMessages(t, f.client)
+		require.Len(t, messages, 1)
+		assert.Equal(t, wsproto.MessageTypeAuthExpiring, messages[0].Type)
+		var data wsproto.AuthExpiringData
+		require.NoError(t, json.Unmarshal(messages[0].Data, &data))
+		assert.True(t, data.Deadline.Equal(expiresAt.Add(ReauthGrace)))
+
+		f.hub.checkSessions(expiresAt.Add(30 * time.Second))
+		assert.Empty(t, drainMessages(t, f.client), "只提醒一次")
+		assert.Zero(t, f.client.closeCode, "宽限期内不断开")
+
+		f.hub.checkSessions(expiresAt.Add(ReauthGrace + time.Second))
+		assert.Equal(t, wsproto.CloseAuthExpired, f.client.closeCode)
+		assert.Empty(t, f.hub.mailboxes, "断开后不再推送邮件")
+	})
+
+	t.Run("重新认证延长会话并重新计算权限", func(t *testing.T) {
+		f := newSessionFixture(t)
+		// 连接期间邮箱转给其他用户，并新建了一个邮箱
+		mb1, err := f.store.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+		other := "user-2"
+		mb1.UserID = &other
+		require.NoError(t, f.store.SaveMailbox(t.Context(), mb1))
+		owner := "user-1"
+		expires := time.Now().Add(time.Hour)
+		require.NoError(t, f.store.SaveMailbox(t.Context(), &domain.Mailbox{ID: "mb-2", Address: "b@temp.mail", UserID: &owner, CreatedAt: time.Now(), ExpiresAt: &expires}))
+
+		f.client.expiresAt = time.Now().Add(-30 * time.Second) // 已过期，仍在宽限期内
+		f.hub.checkSessions(time.Now())
+		drainMessages(t, f.client)
+
+		f.reauth(t, f.token(t, "user-1"))
+		msg := lastMessage(t, f.client)
+		require.Equal(t, wsproto.MessageTypeReauthenticated, msg.Type)
+		var data wsproto.ReauthenticatedData
+		require.NoError(t, json.Unmarshal(msg.Data, &data))
+		assert.Equal(t, []string{"mb-2"}, data.MailboxIDs)
+
+		assert.Equal(t, []string{"mb-2"}, f.client.Permissions)
+		assert.Empty(t, f.client.subscribedMailboxIDs(), "不再有权访问的订阅被移除")
+		assert.NotContains(t, f.hub.mailboxes, "mb-1")
+
+		f.hub.checkSessions(time.Now().Add(ReauthGrace + time.Second))
+		assert.Zero(t, f.client.closeCode, "新令牌延长了会话")
+		f.client.subscribeMailbox(t.Context(), "mb-2", wsproto.SubscribeData{})
+		assert.Equal(t, wsproto.MessageTypeSubscribed, lastMessage(t, f.client).Type)
+	})
+
+	t.Run("重新认证失败时以专用关闭码断开", func(t *testing.T) {
+		for name, token := range map[string]func(f *sessionFixture) string{
+			"无效令牌":    func(*sessionFixture) string { return "not-a-jwt" },
+			"其他用户的令牌": func(f *sessionFixture) string { return f.token(t, "user-2") },
+		} {
+			t.Run(name, func(t *testing.T) {
+				f := newSessionFixture(t)
+				f.reauth(t, token(f))
+				assert.Equal(t, wsproto.CloseReauthFailed, f.client.closeCode)
+				assert.Empty(t, f.hub.mailboxes)
+			})
+		}
+	})
+
+	t.Run("停用用户立即断开其连接", func(t *testing.T) {
+		f := newSessionFixture(t)
+		require.NoError(t, f.store.CreateUser(t.Context(), &domain.User{ID: "admin-1", Email: "admin@example.com", Username: "admin", IsActive: true, Role: domain.RoleAdmin}))
+		admin := service.NewAdminService(f.store, nil)
+		admin.SetSessionCloser(f.hub)
+
+		mailboxClient := newTestClient(f.hub, "mb-1")
+		mailboxClient.IsMailbox, mailboxClient.MailboxID, mailboxClient.Token = true, "mb-1", "mb-token"
+		f.hub.clients[mailboxClient.ID] = mailboxClient
+
+		inactive := false
+		_, err := admin.UpdateUser(t.Context(), service.UpdateUserInput{UserID: "user-1", IsActive: &inactive, OperatorID: "admin-1"})
+		require.NoError(t, err)
+		assert.Equal(t, wsproto.CloseUserDisabled, f.client.closeCode)
+		assert.Zero(t, mailboxClient.closeCode, "邮箱令牌连接不受影响")
+
+		f.reauth(t, f.token(t, "user-1"))
+		assert.Equal(t, wsproto.CloseUserDisabled, f.client.closeCode, "已断开的连接不能重新认证")
+	})
+
+	t.Run("邮箱令牌失效后断开", func(t *testing.T) {
+		f := newSessionFixture(t)
+		mailboxClient := newTestClient(f.hub, "mb-1")
+		mailboxClient.IsMailbox, mailboxClient.MailboxID, mailboxClient.Token = true, "mb-1", "mb-token"
+		f.hub.clients[mailboxClient.ID] = mailboxClient
+
+		f.hub.checkSessions(time.Now().Add(24 * time.Hour))
+		assert.Zero(t, mailboxClient.closeCode, "邮箱令牌不过期")
+
+		mailbox, err := f.store.GetMailbox(t.Context(), "mb-1")
+		require.NoError(t, err)
+		mailbox.Token = "rotated"
+		require.NoError(t, f.store.SaveMailbox(t.Context(), mailbox))
+		f.hub.checkSessions(time.Now())
+		assert.Equal(t, wsproto.CloseTokenRevoked, mailboxClient.closeCode)
+	})
+}
+
+// sendMetricsCounter 记录发送队列指标
+type sendMetricsCounter struct {
+	dropped, evicted atomic.Int64
+}
+
+func (m *sendMetricsCounter) RecordWebSocketDropped() { m.dropped.Add(1) }
+func (m *sendMetricsCounter) RecordWebSocketEvicted() { m.evicted.Add(1) }
+
+// statsOf 读取指定连接的发送队列状态
+func statsOf(t *testing.T, hub *Hub, clientID string) ClientStats {
+	t.Helper()
+	for _, stats := range hub.ClientStats() {
+		if stats.ID == clientID {
+			return stats
+		}
+	}
+	t.Fatalf("连接 %s 不存在", clientID)
+	return ClientStats{}
+}
+
+func TestHub_SlowClientEviction(t *testing.T) {
+	t.Run("连续丢弃超过阈值后驱逐，同一邮箱的其他客户端不受影响", func(t *testing.T) {
+		hub := NewHub(nil, nil, nil)
+		hub.SetSendPolicy(SendPolicy{BufferSize: 4, MaxDropped: 3, MaxBlocked: time.Hour})
+		metrics := &sendMetricsCounter{}
+		hub.SetMetrics(metrics)
+
+		// 不读取队列的客户端（没有写协程）
+		slow := newTestClient(hub, "mb-1")
+		slow.send = make(chan []byte, 4)
+		normal := newTestClient(hub, "mb-1")
+		hub.clients[slow.ID] = slow
+		hub.clients[normal.ID] = normal
+		slow.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		normal.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		drainMessages(t, normal)
+
+		var received []string
+		deliver := func(ids ...string) {
+			for _, id := range ids {
+				ingest(hub, "mb-1", id)
+				got, _ := newMailIDs(t, drainMessages(t, normal))
+				received = append(received, got...)
+			}
+		}
+
+		deliver("m1", "m2", "m3", "m4", "m5")
+		stats := statsOf(t, hub, slow.ID)
+		assert.Equal(t, 4, stats.QueueDepth)
+		assert.Equal(t, 4, stats.QueueCapacity)
+		assert.Equal(t, int64(2), stats.Dropped)
+		assert.Positive(t, stats.BlockedFor)
+		assert.False(t, stats.Evicted, "未达到阈值")
+
+		deliver("m6")
+		stats = statsOf(t, hub, slow.ID)
+		assert.True(t, stats.Evicted)
+		assert.Equal(t, int64(3), stats.Dropped)
+		assert.Equal(t, wsproto.CloseSlowConsumer, slow.closeCode)
+		messages := drainMessages(t, slow)
+		require.Len(t, messages, 1, "队列被清空，只留 overflow 错误")
+		assert.Equal(t, wsproto.MessageTypeError, messages[0].Type)
+		assert.Equal(t, wsproto.ErrCodeOverflow, messages[0].Code)
+
+		deliver("m7")
+		assert.Equal(t, []string{"m1", "m2", "m3", "m4", "m5", "m6", "m7"}, received)
+		assert.Empty(t, drainMessages(t, slow), "驱逐后不再推送")
+		assert.Equal(t, int64(0), statsOf(t, hub, normal.ID).Dropped)
+		assert.Equal(t, int64(3), metrics.dropped.Load())
+		assert.Equal(t, int64(1), metrics.evicted.Load())
+
+		hub.mu.RLock()
+		assert.Len(t, hub.mailboxes["mb-1"], 1)
+		assert.Contains(t, hub.mailboxes["mb-1"], normal.ID)
+		hub.mu.RUnlock()
+	})
+
+	t.Run("驱逐后照常注销，重复注销不会重复关闭通道", func(t *testing.T) {
+		hub := NewHub(nil, nil, nil)
+		hub.SetSendPolicy(SendPolicy{BufferSize: 1, MaxDropped: 1})
+
+		slow := newTestClient(hub, "mb-1")
+		slow.send = make(chan []byte, 1)
+		hub.clients[slow.ID] = slow
+		slow.subscribeMailbox(t.Context(), "mb-1", wsproto.SubscribeData{})
+		ingest(hub, "mb-1", "m1")
+		require.Equal(t, wsproto.CloseSlowConsumer, slow.closeCode)
+
+		// 读协程随连接关闭退出后注销；重复注销（如与关闭 Hub 交错）只处理一次
+		go hub.Run(t.Context())
+		hub.unregister <- slow
+		hub.unregister <- slow
+		assert.Empty(t, hub.ClientStats())
+		hub.mu.RLock()
+		assert.Empty(t, hub.mailboxes)
+		hub.mu.RUnlock()
+		overflow, open := <-slow.send
+		require.True(t, open)
+		assert.Contains(t, string(overflow), `"code":"overflow"`)
+		_, open = <-slow.send
+		assert.False(t, open, "send 通道由注销关闭")
+	})
+
+	t.Run("持续阻塞超过时长后在心跳时驱逐", func(t *testing.T) {
+		hub := NewHub(nil, nil, nil)
+		hub.SetSendPolicy(SendPolicy{BufferSize: 1, MaxDropped: 100, MaxBlocked: 50 * time.Millisecond})
+
+		slow := newTestClient(hub)
+		slow.send = make(chan []byte, 1)
+		hub.clients[slow.ID] = slow
+		hub.pingAllClients()
+		hub.pingAllClients()
+		assert.Zero(t, slow.closeCode, "刚开始阻塞")
+
+		time.Sleep(60 * time.Millisecond)
+		hub.pingAllClients()
+		assert.Equal(t, wsproto.CloseSlowConsumer, slow.closeCode)
+		assert.Equal(t, int64(2), statsOf(t, hub, slow.ID).Dropped)
+	})
+}
@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"tempmail/backend/pkg/wsproto"
+)
+
+// 订阅补发
+//
+// 常见的集成方式是先创建邮箱、触发被测系统发信，之后才连接 WebSocket 订阅，第一封邮件的
+// 通知就此丢失。Hub 为每个邮箱保留最近的 new_mail 事件，新订阅时先补发窗口内的事件
+// （replayed=true），再推送实时事件。
+//
+// 记录事件和建立订阅都在 Hub 锁内完成：订阅前广播的事件只会补发，订阅后广播的事件只会实时推送，
+// 同一连接内既不丢失也不重复，补发事件总在实时事件之前。跨连接是至少一次：重连后窗口内的事件
+// 会再次补发，客户端应按 messageId 去重；订阅时带上已收到的最大 seq（SubscribeData.SinceSeq）
+// 则只补发之后的事件，pkg/wsclient 重连时据此恢复。
+
+// maxRecentEventsPerMailbox 每个邮箱最多保留的事件数
+const maxRecentEventsPerMailbox = 50
+
+// recentEvent 最近广播的一条事件
+type recentEvent struct {
+	at  time.Time
+	seq int64 // 邮件序号，用于按恢复游标过滤
+	msg *wsproto.Message
+}
+
+// SetReplayWindow 设置订阅时补发最近多久内的新邮件事件（0 表示不补发）
+func (h *Hub) SetReplayWindow(window time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.replayWindow = window
+	if window <= 0 {
+		h.recent = make(map[string][]recentEvent)
+	}
+}
+
+// rememberEvent 记录新邮件事件（调用方持有 h.mu 写锁）
+func (h *Hub) rememberEvent(mailboxID string, msg *wsproto.Message) {
+	events := pruneEvents(h.recent[mailboxID], msg.Timestamp.Add(-h.replayWindow))
+	var data wsproto.NewMailData
+	_ = json.Unmarshal(msg.Data, &data)
+	events = append(events, recentEvent{at: msg.Timestamp, seq: data.Seq, msg: msg})
+	if len(events) > maxRecentEventsPerMailbox {
+		events = events[len(events)-maxRecentEventsPerMailbox:]
+	}
+	h.recent[mailboxID] = events
+}
+
+// replayEvents 窗口内序号大于 sinceSeq 的事件副本，标记为补发（调用方持有 h.mu）
+func (h *Hub) replayEvents(mailboxID string, now time.Time, sinceSeq int64) []*wsproto.Message {
+	if h.replayWindow <= 0 {
+		return nil
+	}
+	events := pruneEvents(h.recent[mailboxID], now.Add(-h.replayWindow))
+	replay := make([]*wsproto.Message, 0, len(events))
+	for _, event := range events {
+		if sinceSeq > 0 && event.seq <= sinceSeq {
+			continue
+		}
+		msg := *event.msg
+		msg.Replayed = true
+		replay = append(replay, &msg)
+	}
+	return replay
+}
+
+// pruneRecentEvents 清理过期事件，没有剩余事件的邮箱不再占用内存
+func (h *Hub) pruneRecentEvents(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cutoff := now.Add(-h.replayWindow)
+	for mailboxID, events := range h.recent {
+		if events = pruneEvents(events, cutoff); len(events) == 0 {
+			delete(h.recent, mailboxID)
+		} else {
+			h.recent[mailboxID] = events
+		}
+	}
+}
+
+// pruneEvents 去掉早于 cutoff 的事件（事件按时间顺序追加）
+func pruneEvents(events []recentEvent, cutoff time.Time) []recentEvent {
+	i := 0
+	for i < len(events) && events[i].at.Before(cutoff) {
+		i++
+	}
+	return events[i:]
+}
@@ -0,0 +1,245 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	jwtpkg "tempmail/backend/internal/auth/jwt"
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/pkg/wsproto"
+)
+
+// 会话有效期
+//
+// 权限在建立连接时按访问令牌计算一次，令牌过期后连接并不会自动断开。Hub 的定时任务检查每个
+// JWT 连接的令牌过期时间：到期前 AuthExpiringLead 推送 auth_expiring，客户端需在过期后
+// ReauthGrace 内发送携带新令牌的 reauth 消息，成功后按存储重新计算可访问的邮箱；验证失败或
+// 超时则以专用关闭码断开。邮箱令牌连接不过期，但令牌失效（邮箱删除或令牌变更）后在下一次检查时断开。
+
+const (
+	AuthExpiringLead = 2 * time.Minute // 令牌过期前多久提醒重新认证
+	ReauthGrace      = time.Minute     // 令牌过期后等待重新认证的时长
+)
+
+var (
+	errUserMismatch = errors.New("token belongs to another user") // 重新认证的令牌属于其他用户
+	errUserInactive = errors.New("user is not active")
+)
+
+// UserStore 用户查询（可选，存储实现时重新认证会拒绝已停用的用户）
+type UserStore interface {
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+}
+
+// tokenExpiry 令牌过期时间（未设置过期时间返回零值，表示不过期）
+func tokenExpiry(claims *jwtpkg.Claims) time.Time {
+	if claims.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return claims.ExpiresAt.Time
+}
+
+// userActive 用户是否仍可使用（存储不支持查询用户时视为可用）
+func (h *Hub) userActive(ctx context.Context, userID string) bool {
+	users, ok := h.mailboxStore.(UserStore)
+	if !ok {
+		return true
+	}
+	user, err := users.GetUserByID(ctx, userID)
+	return err == nil && user != nil && user.IsActive
+}
+
+// checkSessions 检查连接的认证状态（由定时任务调用）
+func (h *Hub) checkSessions(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, client := range h.clients {
+		switch {
+		case client.IsMailbox:
+			if _, err := h.validateMailboxToken(context.Background(), client.Token, client.MailboxID); err != nil {
+				h.closeSession(client, wsproto.CloseTokenRevoked, "mailbox token revoked")
+			}
+		case client.UserID != "":
+			h.checkExpiry(client, now)
+		}
+	}
+}
+
+// checkExpiry 令牌即将过期时提醒，超过宽限期后断开（调用方持有 h.mu 写锁）
+func (h *Hub) checkExpiry(client *Client, now time.Time) {
+	client.mu.Lock()
+	expiresAt := client.expiresAt
+	notify := !expiresAt.IsZero() && !client.expiringSent && !now.Before(expiresAt.Add(-AuthExpiringLead))
+	if notify {
+		client.expiringSent = true
+	}
+	client.mu.Unlock()
+
+	if expiresAt.IsZero() {
+		return
+	}
+	if now.After(expiresAt.Add(ReauthGrace)) {
+		h.closeSession(client, wsproto.CloseAuthExpired, "authentication expired")
+		return
+	}
+	if notify {
+		data, err := json.Marshal(wsproto.AuthExpiringData{ExpiresAt: expiresAt, Deadline: expiresAt.Add(ReauthGrace)})
+		if err != nil {
+			h.log.Error("failed to marshal auth expiring data", zap.Error(err))
+			return
+		}
+		client.sendMessage(&wsproto.Message{Type: wsproto.MessageTypeAuthExpiring, Data: data, Timestamp: now})
+	}
+}
+
+// CloseByUserID 断开用户的所有 JWT 连接（用户被停用或删除时调用）
+func (h *Hub) CloseByUserID(userID, reason string) {
+	if userID == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	closed := 0
+	for _, client := range h.clients {
+		if client.IsMailbox || client.UserID != userID {
+			continue
+		}
+		h.closeSession(client, wsproto.CloseUserDisabled, reason)
+		closed++
+	}
+	h.log.Info("user connections closed", zap.String("userID", userID), zap.Int("clients", closed))
+}
+
+// closeSession 撤销客户端的全部订阅并以指定关闭码断开（调用方持有 h.mu 写锁）
+//
+// 客户端仍留在 clients 中，读协程随连接关闭退出后照常注销。
+func (h *Hub) closeSession(client *Client, code int, reason string) {
+	if !h.revokeSession(client, code, reason) {
+		return
+	}
+
+	h.log.Info("closing websocket session",
+		zap.String("clientID", client.ID),
+		zap.String("userID", client.UserID),
+		zap.Int("code", code),
+		zap.String("reason", reason))
+
+	if client.conn != nil {
+		// WriteControl 可与写协程并发调用
+		_ = client.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(10*time.Second))
+		_ = client.conn.Close()
+	}
+}
+
+// revokeSession 记录关闭码并撤销客户端的全部订阅，已关闭过时返回 false（调用方持有 h.mu 写锁）
+func (h *Hub) revokeSession(client *Client, code int, reason string) bool {
+	client.mu.Lock()
+	if client.closeCode != 0 {
+		client.mu.Unlock()
+		return false
+	}
+	client.closeCode, client.closeReason = code, reason
+	subscribed := client.mailboxIDs
+	client.mailboxIDs = make(map[string]bool)
+	client.publicIDs = make(map[string]bool)
+	client.Permissions = nil
+	client.mu.Unlock()
+
+	for mailboxID := range subscribed {
+		h.removeSubscriber(mailboxID, client.ID)
+	}
+	return true
+}
+
+// removeSubscriber 从邮箱订阅者中移除客户端（调用方持有 h.mu 写锁）
+func (h *Hub) removeSubscriber(mailboxID, clientID string) {
+	if clients, exists := h.mailboxes[mailboxID]; exists {
+		delete(clients, clientID)
+		if len(clients) == 0 {
+			delete(h.mailboxes, mailboxID)
+		}
+	}
+}
+
+// reauthenticate 使用新令牌延长会话，并按存储重新计算可访问的邮箱
+//
+// 不再有权访问的邮箱订阅被移除（公开订阅保留）；令牌无效、属于其他用户或用户已停用时断开连接。
+func (c *Client) reauthenticate(ctx context.Context, msg *wsproto.Message) {
+	if c.IsMailbox || c.UserID == "" {
+		c.sendError(wsproto.ErrCodeInvalidRequest, "reauth requires a user session")
+		return
+	}
+
+	var data wsproto.ReauthData
+	if len(msg.Data) > 0 {
+		_ = json.Unmarshal(msg.Data, &data)
+	}
+	claims, err := c.hub.jwtClaims(ctx, data.Token)
+	if err == nil && claims.UserID != c.UserID {
+		err = errUserMismatch
+	}
+	if err == nil && !c.hub.userActive(ctx, c.UserID) {
+		err = errUserInactive
+	}
+	if err != nil {
+		c.log.Warn("websocket reauthentication failed", zap.String("clientID", c.ID), zap.String("userID", c.UserID), zap.Error(err))
+		c.hub.mu.Lock()
+		c.hub.closeSession(c, wsproto.CloseReauthFailed, "reauthentication failed")
+		c.hub.mu.Unlock()
+		return
+	}
+
+	mailboxes := c.hub.userMailboxes(ctx, c.UserID)
+	permissions := make([]string, len(mailboxes))
+	allowed := make(map[string]bool, len(mailboxes))
+	for i, mb := range mailboxes {
+		permissions[i] = mb.ID
+		allowed[mb.ID] = true
+	}
+	expiresAt := tokenExpiry(claims)
+
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+
+	c.mu.Lock()
+	if c.closeCode != 0 {
+		c.mu.Unlock()
+		return
+	}
+	c.Token = data.Token
+	c.Permissions = permissions
+	c.expiresAt = expiresAt
+	c.expiringSent = false
+	var dropped []string
+	for mailboxID := range c.mailboxIDs {
+		if !c.publicIDs[mailboxID] && !allowed[mailboxID] {
+			delete(c.mailboxIDs, mailboxID)
+			dropped = append(dropped, mailboxID)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, mailboxID := range dropped {
+		c.hub.removeSubscriber(mailboxID, c.ID)
+	}
+
+	payload, err := json.Marshal(wsproto.ReauthenticatedData{ExpiresAt: expiresAt, MailboxIDs: permissions})
+	if err != nil {
+		c.log.Error("failed to marshal reauthenticated data", zap.Error(err))
+		return
+	}
+	c.sendMessage(&wsproto.Message{Type: wsproto.MessageTypeReauthenticated, Data: payload, Timestamp: time.Now()})
+
+	c.log.Info("websocket session reauthenticated",
+		zap.String("clientID", c.ID),
+		zap.String("userID", c.UserID),
+		zap.Int("mailboxCount", len(permissions)),
+		zap.Int("dropped", len(dropped)))
+}
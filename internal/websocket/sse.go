@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tempmail/backend/pkg/wsproto"
+)
+
+// sseRetry 建议 EventSource 断线后重连的间隔
+const sseRetry = 3 * time.Second
+
+// HandleSSE 以 Server-Sent Events 推送单个邮箱的事件（需在邮箱令牌认证之后使用）
+//
+// 与 WebSocket 共用 Hub 的订阅、补发和背压：事件名为消息类型，data 为与 WebSocket 相同的消息 JSON，
+// new_mail 的 id 为邮件序号，重连时浏览器带回的 Last-Event-ID（或 lastEventId 参数）作为恢复游标。
+// Hub 心跳写为注释行保持连接；邮箱被删除、停用或客户端被驱逐时写出最后一条事件后结束。
+func HandleSSE(hub *Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mailboxID := c.Param("id")
+		cursor := c.GetHeader("Last-Event-ID")
+		if cursor == "" {
+			cursor = c.Query("lastEventId")
+		}
+		sinceSeq, _ := strconv.ParseInt(cursor, 10, 64)
+
+		// 流式响应不受服务器写超时限制
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+		header := c.Writer.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+		c.Status(http.StatusOK)
+
+		stream := hub.OpenStream(mailboxID, c.GetString("userID"), sinceSeq)
+		defer stream.Close()
+		stream.Touch(c.Request.Context())
+
+		fmt.Fprintf(c.Writer, "retry: %d\n\n", sseRetry.Milliseconds())
+		c.Writer.Flush()
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case payload, ok := <-stream.Events():
+				if !ok {
+					return
+				}
+				if done := writeEvent(c.Request.Context(), c.Writer, stream, payload); done {
+					c.Writer.Flush()
+					return
+				}
+				c.Writer.Flush()
+			case <-stream.Evicted():
+				// 被驱逐：写出队列中剩下的 overflow 错误后结束
+				for {
+					select {
+					case payload, ok := <-stream.Events():
+						if ok {
+							writeEvent(c.Request.Context(), c.Writer, stream, payload)
+							continue
+						}
+					default:
+					}
+					break
+				}
+				c.Writer.Flush()
+				return
+			}
+		}
+	}
+}
+
+// writeEvent 把一条 Hub 消息写为 SSE 事件，返回流是否应结束
+//
+// 心跳写为注释行，并在此时检查会话是否已被服务端关闭（邮箱停用、用户停用）。
+func writeEvent(ctx context.Context, w io.Writer, stream *Stream, payload []byte) bool {
+	var msg wsproto.Message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return false
+	}
+
+	switch msg.Type {
+	case wsproto.MessageTypePing:
+		if code, reason := stream.Closed(); code != 0 {
+			data, _ := json.Marshal(&wsproto.Message{Type: wsproto.MessageTypeError, Error: reason, Timestamp: time.Now()})
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", wsproto.MessageTypeError, data)
+			return true
+		}
+		fmt.Fprint(w, ": ping\n\n")
+		stream.Touch(ctx)
+		return false
+	case wsproto.MessageTypeNewMail:
+		var data wsproto.NewMailData
+		if json.Unmarshal(msg.Data, &data) == nil && data.Seq > 0 {
+			fmt.Fprintf(w, "id: %d\n", data.Seq)
+		}
+	case wsproto.MessageTypeSubscribed, wsproto.MessageTypeMailboxUpdate:
+	case wsproto.MessageTypeMailboxDeleted, wsproto.MessageTypeMailboxSuspended, wsproto.MessageTypeError:
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, payload)
+		return true
+	default:
+		return false // 其他事件只对 WebSocket 连接有意义
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, payload)
+	return false
+}
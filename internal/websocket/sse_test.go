@@ -0,0 +1,123 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/domain"
+	"tempmail/backend/pkg/wsproto"
+)
+
+// sseEvent 解析出的一条 SSE 事件
+type sseEvent struct {
+	id    string
+	event string
+	data  wsproto.Message
+}
+
+// readSSEEvent 读取下一条事件，跳过 retry 和注释行
+func readSSEEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if ev.event != "" {
+				return ev
+			}
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.data))
+		}
+	}
+}
+
+func TestHandleSSE(t *testing.T) {
+	hub := NewHub(nil, nil, nil)
+	hub.SetReplayWindow(5 * time.Minute)
+
+	engine := gin.New()
+	engine.GET("/v1/mailboxes/:id/events", HandleSSE(hub))
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	// ingestSeq 同步广播带序号的新邮件
+	ingestSeq := func(mailboxID, messageID string, seq int64) {
+		hub.NotifyNewMail(t.Context(), mailboxID, &domain.Message{ID: messageID, MailboxID: mailboxID, Seq: seq})
+		b := <-hub.broadcast
+		hub.broadcastToMailbox(b.MailboxID, b.Message)
+	}
+	open := func(t *testing.T, lastEventID string) (*bufio.Reader, context.CancelFunc) {
+		t.Helper()
+		ctx, cancel := context.WithCancel(t.Context())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/mailboxes/mb-1/events", nil)
+		require.NoError(t, err)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		r := bufio.NewReader(resp.Body)
+		require.Equal(t, "subscribed", readSSEEvent(t, r).event)
+		return r, cancel
+	}
+
+	ingestSeq("mb-1", "first", 1)
+	ingestSeq("mb-1", "second", 2)
+
+	t.Run("按 Last-Event-ID 补发并推送新事件", func(t *testing.T) {
+		r, cancel := open(t, "1")
+		defer cancel()
+
+		replayed := readSSEEvent(t, r)
+		assert.Equal(t, "new_mail", replayed.event)
+		assert.Equal(t, "2", replayed.id)
+		assert.True(t, replayed.data.Replayed)
+
+		ingestSeq("mb-1", "third", 3)
+		live := readSSEEvent(t, r)
+		assert.Equal(t, "3", live.id)
+		assert.False(t, live.data.Replayed)
+		var data wsproto.NewMailData
+		require.NoError(t, json.Unmarshal(live.data.Data, &data))
+		assert.Equal(t, "third", data.MessageID)
+
+		go hub.NotifyMailboxUpdate(&domain.Mailbox{ID: "mb-1", Unread: 3, TotalCount: 3})
+		b := <-hub.broadcast
+		hub.broadcastToMailbox(b.MailboxID, b.Message)
+		assert.Equal(t, "mailbox_update", readSSEEvent(t, r).event)
+	})
+
+	t.Run("断开后注销客户端", func(t *testing.T) {
+		assert.Eventually(t, func() bool { return hub.ConnectionCount() == 0 }, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("邮箱删除后结束流", func(t *testing.T) {
+		r, cancel := open(t, "3")
+		defer cancel()
+
+		hub.NotifyMailboxDeleted("mb-1")
+		assert.Equal(t, "mailbox_deleted", readSSEEvent(t, r).event)
+		_, err := r.ReadString('\n')
+		assert.Error(t, err, "服务端关闭连接")
+		assert.Eventually(t, func() bool { return hub.ConnectionCount() == 0 }, 2*time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"tempmail/backend/pkg/wsproto"
+)
+
+// Stream 单个邮箱的进程内事件流（SSE、gRPC 等非 WebSocket 传输使用）
+//
+// 与 WebSocket 连接共用 Hub 的订阅、补发和背压：Events 中依次是 subscribed 确认、补发的 new_mail
+// 和之后的实时事件（均为 wsproto.Message JSON），以及 Hub 每 30 秒一次的 ping。读取过慢被驱逐时
+// Evicted 关闭（队列中最后一条是 overflow 错误），邮箱或用户被停用时 Closed 返回关闭码。
+type Stream struct {
+	client *Client
+}
+
+// OpenStream 注册事件流并订阅邮箱，sinceSeq 大于 0 时只补发序号更大的新邮件
+//
+// userID 为空表示凭邮箱令牌访问；访问权限由调用方检查。用完必须调用 Close。
+func (h *Hub) OpenStream(mailboxID, userID string, sinceSeq int64) *Stream {
+	client := &Client{
+		ID:          generateClientID(),
+		hub:         h,
+		mailboxIDs:  map[string]bool{mailboxID: true},
+		publicIDs:   make(map[string]bool),
+		log:         h.log,
+		UserID:      userID,
+		MailboxID:   mailboxID,
+		IsMailbox:   userID == "",
+		Permissions: []string{mailboxID},
+		overflow:    make(chan struct{}),
+	}
+
+	// 确认和补发事件在 Hub 锁内入队，之后广播的实时事件排在它们之后
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client.send = make(chan []byte, h.sendPolicy.BufferSize)
+	h.clients[client.ID] = client
+	if h.mailboxes[mailboxID] == nil {
+		h.mailboxes[mailboxID] = make(map[string]*Client)
+	}
+	h.mailboxes[mailboxID][client.ID] = client
+
+	client.sendMessage(&wsproto.Message{
+		Type:            wsproto.MessageTypeSubscribed,
+		MailboxID:       mailboxID,
+		Timestamp:       time.Now(),
+		ProtocolVersion: wsproto.Version,
+	})
+	for _, msg := range h.replayEvents(mailboxID, time.Now(), sinceSeq) {
+		client.sendMessage(msg)
+	}
+
+	h.log.Info("event stream opened",
+		zap.String("clientID", client.ID),
+		zap.String("mailboxID", mailboxID),
+		zap.String("userID", userID))
+	return &Stream{client: client}
+}
+
+// Events 事件队列，Close 或 Hub 停止后关闭
+func (s *Stream) Events() <-chan []byte {
+	return s.client.send
+}
+
+// Evicted 读取过慢被驱逐时关闭
+func (s *Stream) Evicted() <-chan struct{} {
+	return s.client.overflow
+}
+
+// Closed 会话被服务端关闭时的关闭码和原因（邮箱停用、用户停用），未关闭时 code 为 0
+func (s *Stream) Closed() (code int, reason string) {
+	s.client.mu.RLock()
+	defer s.client.mu.RUnlock()
+	return s.client.closeCode, s.client.closeReason
+}
+
+// Touch 记录邮箱访问（闲置检测）
+func (s *Stream) Touch(ctx context.Context) {
+	s.client.hub.recordActivity(ctx, s.client.MailboxID)
+}
+
+// Close 取消订阅并注销，可重复调用
+func (s *Stream) Close() {
+	s.client.hub.removeClient(s.client)
+}
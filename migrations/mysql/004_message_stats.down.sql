@@ -0,0 +1,6 @@
+-- MySQL Rollback: 收件统计
+
+DROP INDEX `idx_messages_mailbox_received` ON `messages`;
+
+ALTER TABLE `messages`
+    DROP COLUMN `size`;
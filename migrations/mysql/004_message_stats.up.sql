@@ -0,0 +1,16 @@
+-- MySQL Migration: 收件统计
+-- 邮件大小字段与 (mailbox_id, received_at) 复合索引，供按小时聚合使用
+-- 注意：received_at 若已由 GORM 自动迁移创建，请跳过第 1 步
+
+-- 1. 接收时间（统一写入 UTC）
+ALTER TABLE `messages`
+    ADD COLUMN `received_at` DATETIME(3) NULL COMMENT '接收时间（UTC）';
+
+UPDATE `messages` SET `received_at` = `created_at` WHERE `received_at` IS NULL;
+
+-- 2. 邮件大小
+ALTER TABLE `messages`
+    ADD COLUMN `size` BIGINT DEFAULT 0 COMMENT '邮件大小（字节）';
+
+-- 3. 复合索引
+CREATE INDEX `idx_messages_mailbox_received` ON `messages` (`mailbox_id`, `received_at`);
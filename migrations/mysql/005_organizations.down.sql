@@ -0,0 +1,13 @@
+-- MySQL Rollback: 组织/团队
+
+ALTER TABLE `user_domains`
+    DROP INDEX `idx_user_domains_org_id`,
+    DROP COLUMN `org_id`;
+
+ALTER TABLE `mailboxes`
+    DROP INDEX `idx_mailboxes_org_id`,
+    DROP COLUMN `org_id`;
+
+DROP TABLE IF EXISTS `org_invites`;
+DROP TABLE IF EXISTS `org_members`;
+DROP TABLE IF EXISTS `organizations`;
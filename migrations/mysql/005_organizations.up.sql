@@ -0,0 +1,44 @@
+-- MySQL Migration: 组织/团队
+-- 组织、成员、邀请表，以及邮箱/用户域名的可选组织归属
+-- 注意：tags、webhooks 表由 GORM 自动迁移创建，其 org_id 列同样由 GORM 添加
+
+CREATE TABLE IF NOT EXISTS `organizations` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '组织ID (UUID)',
+    `name` VARCHAR(100) NOT NULL COMMENT '组织名称',
+    `owner_id` VARCHAR(36) NOT NULL COMMENT '创建者（不可移除）',
+    `tier` VARCHAR(20) DEFAULT 'free' COMMENT '组织等级（决定组织配额）',
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    INDEX `idx_organizations_owner_id` (`owner_id`),
+    FOREIGN KEY (`owner_id`) REFERENCES `users`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='组织表';
+
+CREATE TABLE IF NOT EXISTS `org_members` (
+    `org_id` VARCHAR(36) NOT NULL COMMENT '组织ID',
+    `user_id` VARCHAR(36) NOT NULL COMMENT '用户ID',
+    `role` VARCHAR(20) DEFAULT 'member' COMMENT '角色: owner/member',
+    `joined_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '加入时间',
+    PRIMARY KEY (`org_id`, `user_id`),
+    INDEX `idx_org_members_user_id` (`user_id`),
+    FOREIGN KEY (`org_id`) REFERENCES `organizations`(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='组织成员表';
+
+CREATE TABLE IF NOT EXISTS `org_invites` (
+    `token` VARCHAR(64) PRIMARY KEY COMMENT '加入令牌（一次性）',
+    `org_id` VARCHAR(36) NOT NULL COMMENT '组织ID',
+    `email` VARCHAR(255) NOT NULL COMMENT '受邀邮箱',
+    `invited_by` VARCHAR(36) COMMENT '邀请人',
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `expires_at` TIMESTAMP NOT NULL COMMENT '过期时间',
+    INDEX `idx_org_invites_org_id` (`org_id`),
+    FOREIGN KEY (`org_id`) REFERENCES `organizations`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='组织邀请表';
+
+ALTER TABLE `mailboxes`
+    ADD COLUMN `org_id` VARCHAR(36) NULL COMMENT '所属组织（组织成员共享）',
+    ADD INDEX `idx_mailboxes_org_id` (`org_id`);
+
+ALTER TABLE `user_domains`
+    ADD COLUMN `org_id` VARCHAR(36) NULL COMMENT '所属组织',
+    ADD INDEX `idx_user_domains_org_id` (`org_id`);
@@ -0,0 +1,4 @@
+-- MySQL Rollback: 用户域名过期宽限期
+
+ALTER TABLE `user_domains`
+    DROP COLUMN `grace_notices`;
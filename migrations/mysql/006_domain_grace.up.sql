@@ -0,0 +1,5 @@
+-- MySQL Migration: 用户域名过期宽限期
+-- 记录宽限期内已发送的过期提醒次数，续期后清零
+
+ALTER TABLE `user_domains`
+    ADD COLUMN `grace_notices` INT DEFAULT 0 COMMENT '宽限期内已发送的过期提醒次数';
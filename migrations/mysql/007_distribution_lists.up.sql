@@ -0,0 +1,31 @@
+-- MySQL Migration: 分发列表
+-- 发往列表地址的邮件复制到每个成员邮箱，投递报告记录每个成员的结果
+
+CREATE TABLE IF NOT EXISTS `distribution_lists` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '列表ID',
+    `user_id` VARCHAR(36) NOT NULL COMMENT '创建者',
+    `org_id` VARCHAR(36) NULL COMMENT '所属组织',
+    `name` VARCHAR(100) COMMENT '名称',
+    `address` VARCHAR(255) NOT NULL COMMENT '列表地址',
+    `members` JSON COMMENT '成员邮箱ID或嵌套列表ID（有序）',
+    `is_active` BOOLEAN DEFAULT TRUE COMMENT '是否启用',
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    UNIQUE KEY `idx_distribution_lists_address` (`address`),
+    INDEX `idx_distribution_lists_user_id` (`user_id`),
+    INDEX `idx_distribution_lists_org_id` (`org_id`),
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='分发列表';
+
+CREATE TABLE IF NOT EXISTS `distribution_list_deliveries` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '报告ID',
+    `list_id` VARCHAR(36) NOT NULL COMMENT '分发列表ID',
+    `from` VARCHAR(255) COMMENT '发件人',
+    `subject` VARCHAR(500) COMMENT '主题',
+    `delivered` JSON COMMENT '投递成功的邮箱ID',
+    `failures` JSON COMMENT '投递失败的成员及原因',
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '投递时间',
+    INDEX `idx_distribution_list_deliveries_list_id` (`list_id`),
+    INDEX `idx_distribution_list_deliveries_created_at` (`created_at`),
+    FOREIGN KEY (`list_id`) REFERENCES `distribution_lists`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='分发列表投递报告';
@@ -0,0 +1,13 @@
+-- MySQL Rollback: 垃圾邮件评分
+
+ALTER TABLE `messages`
+    DROP INDEX `idx_messages_quarantined`,
+    DROP INDEX `idx_messages_spam_score`,
+    DROP COLUMN `quarantined`,
+    DROP COLUMN `spam_symbols`,
+    DROP COLUMN `spam_action`,
+    DROP COLUMN `spam_score`;
+
+ALTER TABLE `mailboxes`
+    DROP COLUMN `spam_reject_score`,
+    DROP COLUMN `spam_quarantine_score`;
@@ -0,0 +1,14 @@
+-- MySQL Migration: 垃圾邮件评分
+-- 邮件记录评分、建议动作和命中规则，超过软阈值的邮件进入隔离区；邮箱可自定义阈值
+
+ALTER TABLE `messages`
+    ADD COLUMN `spam_score` DOUBLE DEFAULT 0 COMMENT '垃圾邮件评分（未评分时为 0）',
+    ADD COLUMN `spam_action` VARCHAR(32) NULL COMMENT '评分服务建议的动作',
+    ADD COLUMN `spam_symbols` JSON NULL COMMENT '命中的规则',
+    ADD COLUMN `quarantined` BOOLEAN DEFAULT FALSE COMMENT '超过软阈值，投递到隔离区',
+    ADD INDEX `idx_messages_spam_score` (`spam_score`),
+    ADD INDEX `idx_messages_quarantined` (`quarantined`);
+
+ALTER TABLE `mailboxes`
+    ADD COLUMN `spam_quarantine_score` DOUBLE NULL COMMENT '自定义软阈值（为空时使用系统配置）',
+    ADD COLUMN `spam_reject_score` DOUBLE NULL COMMENT '自定义硬阈值（为空时使用系统配置）';
@@ -0,0 +1,3 @@
+-- MySQL Rollback: 邮件脱敏副本
+
+DROP TABLE IF EXISTS `message_redactions`;
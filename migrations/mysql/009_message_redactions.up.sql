@@ -0,0 +1,17 @@
+-- MySQL Migration: 邮件脱敏副本
+-- 分享邮件前生成的脱敏副本，每封邮件一份，记录使用的规则和规则版本
+
+CREATE TABLE IF NOT EXISTS `message_redactions` (
+    `message_id` VARCHAR(36) PRIMARY KEY COMMENT '邮件ID',
+    `mailbox_id` VARCHAR(36) NOT NULL COMMENT '邮箱ID',
+    `rules_version` INT NOT NULL DEFAULT 1 COMMENT '生成时的内置规则版本',
+    `rules` JSON COMMENT '内置规则',
+    `patterns` JSON COMMENT '自定义正则',
+    `subject` VARCHAR(500) COMMENT '脱敏后的主题',
+    `from` VARCHAR(255) COMMENT '脱敏后的发件人',
+    `text` MEDIUMTEXT COMMENT '脱敏后的纯文本正文',
+    `html` MEDIUMTEXT COMMENT '脱敏并清理后的 HTML 正文',
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '生成时间',
+    INDEX `idx_message_redactions_mailbox_id` (`mailbox_id`),
+    FOREIGN KEY (`message_id`) REFERENCES `messages`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='邮件脱敏副本';
@@ -0,0 +1,11 @@
+-- MySQL Rollback: 域名黑洞模式
+
+ALTER TABLE `system_domains`
+    DROP COLUMN `sink_enabled`,
+    DROP COLUMN `sink_sample_rate`,
+    DROP COLUMN `sink_diagnostics_mailbox_id`;
+
+ALTER TABLE `user_domains`
+    DROP COLUMN `sink_enabled`,
+    DROP COLUMN `sink_sample_rate`,
+    DROP COLUMN `sink_diagnostics_mailbox_id`;
@@ -0,0 +1,12 @@
+-- MySQL Migration: 域名黑洞模式
+-- 开启后接收域名下任意收件人，邮件不保存，只在 Redis 中累计汇总统计
+
+ALTER TABLE `system_domains`
+    ADD COLUMN `sink_enabled` BOOLEAN DEFAULT FALSE COMMENT '黑洞模式：接收任意收件人，不保存邮件',
+    ADD COLUMN `sink_sample_rate` INT DEFAULT 0 COMMENT '每 N 封抽样 1 封投递到诊断邮箱，0 表示不抽样',
+    ADD COLUMN `sink_diagnostics_mailbox_id` VARCHAR(36) DEFAULT NULL COMMENT '抽样邮件投递的邮箱';
+
+ALTER TABLE `user_domains`
+    ADD COLUMN `sink_enabled` BOOLEAN DEFAULT FALSE COMMENT '黑洞模式（仅独享模式的已验证域名生效）',
+    ADD COLUMN `sink_sample_rate` INT DEFAULT 0 COMMENT '每 N 封抽样 1 封投递到诊断邮箱，0 表示不抽样',
+    ADD COLUMN `sink_diagnostics_mailbox_id` VARCHAR(36) DEFAULT NULL COMMENT '抽样邮件投递的邮箱';
@@ -0,0 +1,8 @@
+-- MySQL Rollback: 闲置邮箱检测
+
+ALTER TABLE `mailboxes`
+    DROP INDEX `idx_mailboxes_last_accessed_at`,
+    DROP COLUMN `last_accessed_at`,
+    DROP COLUMN `idle_since`,
+    DROP COLUMN `idle_shortened`,
+    DROP COLUMN `idle_original_expires_at`;
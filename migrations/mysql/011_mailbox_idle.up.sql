@@ -0,0 +1,9 @@
+-- MySQL Migration: 闲置邮箱检测
+-- 记录邮箱最近一次被访问的时间（API 读取、WebSocket 订阅），SMTP 投递不算访问
+
+ALTER TABLE `mailboxes`
+    ADD COLUMN `last_accessed_at` TIMESTAMP NULL COMMENT '最近一次被访问的时间，每小时最多更新一次',
+    ADD COLUMN `idle_since` TIMESTAMP NULL COMMENT '被判定为闲置并已提醒的时间，再次访问后清空',
+    ADD COLUMN `idle_shortened` BOOLEAN DEFAULT FALSE COMMENT '闲置时是否缩短了有效期',
+    ADD COLUMN `idle_original_expires_at` TIMESTAMP NULL COMMENT '缩短前的过期时间，再次访问时恢复',
+    ADD INDEX `idx_mailboxes_last_accessed_at` (`last_accessed_at`);
@@ -0,0 +1,8 @@
+-- MySQL Rollback: 公开收件箱
+
+ALTER TABLE `mailboxes`
+    DROP INDEX `idx_mailboxes_is_public`,
+    DROP COLUMN `is_public`;
+
+ALTER TABLE `system_domains`
+    DROP COLUMN `allow_public_inboxes`;
@@ -0,0 +1,9 @@
+-- MySQL Migration: 公开收件箱
+-- 公开收件箱无需令牌即可只读查看，邮件按固定短时限自动删除
+
+ALTER TABLE `mailboxes`
+    ADD COLUMN `is_public` BOOLEAN DEFAULT FALSE COMMENT '是否为公开收件箱（管理员设置，或在允许的域名上创建时指定）',
+    ADD INDEX `idx_mailboxes_is_public` (`is_public`);
+
+ALTER TABLE `system_domains`
+    ADD COLUMN `allow_public_inboxes` BOOLEAN DEFAULT FALSE COMMENT '是否允许创建邮箱时直接设为公开收件箱';
@@ -0,0 +1,5 @@
+-- MySQL Rollback: 登录防暴力破解
+
+ALTER TABLE `users`
+    DROP COLUMN `locked_until`,
+    DROP COLUMN `recent_login_ips`;
@@ -0,0 +1,6 @@
+-- MySQL Migration: 登录防暴力破解
+-- 连续登录失败锁定账户；记录最近登录 IP，新 IP 登录时提醒用户
+
+ALTER TABLE `users`
+    ADD COLUMN `locked_until` TIMESTAMP NULL COMMENT '连续登录失败后锁定至该时间（为空表示未锁定）',
+    ADD COLUMN `recent_login_ips` JSON COMMENT '最近成功登录的 IP 列表（最多保留 10 个）';
@@ -0,0 +1,5 @@
+-- MySQL Rollback: 附件实际类型判断
+
+ALTER TABLE `attachments`
+    DROP COLUMN `detected_content_type`,
+    DROP COLUMN `content_type_mismatch`;
@@ -0,0 +1,6 @@
+-- MySQL Migration: 附件实际类型判断
+-- 入库时按内容魔数判断附件实际类型，下载按实际类型返回；声明类型与实际类型不一致时标记提示
+
+ALTER TABLE `attachments`
+    ADD COLUMN `detected_content_type` VARCHAR(100) COMMENT '按内容魔数判断的实际类型',
+    ADD COLUMN `content_type_mismatch` BOOLEAN DEFAULT FALSE COMMENT '声明类型与实际类型存在安全相关的不一致（如伪装成图片的可执行文件）';
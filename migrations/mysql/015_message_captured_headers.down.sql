@@ -0,0 +1,4 @@
+-- MySQL Rollback: 邮件自定义头
+
+ALTER TABLE `messages`
+    DROP COLUMN `captured_headers`;
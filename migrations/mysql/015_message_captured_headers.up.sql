@@ -0,0 +1,5 @@
+-- MySQL Migration: 邮件自定义头
+-- 收信时按名单（mailbox.captured_headers / 系统配置）保存 X-Test-Run-ID 等头，邮件列表和搜索可按 header.<名称>=<值> 精确过滤
+
+ALTER TABLE `messages`
+    ADD COLUMN `captured_headers` JSON NULL COMMENT '收信时按名单保存的头（键为规范化头名）';
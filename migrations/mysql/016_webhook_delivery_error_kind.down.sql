@@ -0,0 +1,4 @@
+-- MySQL Rollback: Webhook 投递失败类型
+
+ALTER TABLE `webhook_deliveries`
+    DROP COLUMN `error_kind`;
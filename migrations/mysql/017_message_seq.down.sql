@@ -0,0 +1,6 @@
+-- MySQL Rollback: 邮件入库序号
+
+DROP INDEX `idx_messages_mailbox_seq` ON `messages`;
+DROP TABLE IF EXISTS `message_sequences`;
+ALTER TABLE `messages`
+    DROP COLUMN `seq`;
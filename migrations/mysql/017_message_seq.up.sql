@@ -0,0 +1,26 @@
+-- MySQL Migration: 邮件入库序号
+-- 每个邮箱内单调递增的序号，在保存邮件的事务内分配；列表和搜索按序号排序，WebSocket 通知携带序号供客户端检测遗漏
+
+ALTER TABLE `messages`
+    ADD COLUMN `seq` BIGINT NOT NULL DEFAULT 0 COMMENT '邮箱内单调递增的入库序号' AFTER `mailbox_id`;
+
+CREATE TABLE IF NOT EXISTS `message_sequences` (
+    `mailbox_id` VARCHAR(36) NOT NULL,
+    `last_seq` BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (`mailbox_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='邮箱最近分配的邮件序号';
+
+-- 已有邮件按创建时间补齐序号
+UPDATE `messages` m
+JOIN (
+    SELECT `id`, ROW_NUMBER() OVER (PARTITION BY `mailbox_id` ORDER BY `created_at`, `id`) AS rn
+    FROM `messages`
+) ordered ON m.`id` = ordered.`id`
+SET m.`seq` = ordered.rn
+WHERE m.`seq` = 0;
+
+INSERT INTO `message_sequences` (`mailbox_id`, `last_seq`)
+SELECT `mailbox_id`, MAX(`seq`) FROM `messages` GROUP BY `mailbox_id`
+ON DUPLICATE KEY UPDATE `last_seq` = GREATEST(`last_seq`, VALUES(`last_seq`));
+
+CREATE INDEX `idx_messages_mailbox_seq` ON `messages`(`mailbox_id`, `seq`);
@@ -0,0 +1,8 @@
+-- MySQL Rollback: 邮箱 Webhook
+
+DELETE FROM `webhook_deliveries` WHERE `webhook_id` IN (SELECT `id` FROM `webhooks` WHERE `owner_type` = 'mailbox');
+DELETE FROM `webhooks` WHERE `owner_type` = 'mailbox';
+DROP INDEX `idx_webhooks_owner` ON `webhooks`;
+ALTER TABLE `webhooks`
+    DROP COLUMN `owner_id`,
+    DROP COLUMN `owner_type`;
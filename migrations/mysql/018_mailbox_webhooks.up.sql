@@ -0,0 +1,8 @@
+-- MySQL Migration: 邮箱 Webhook
+-- 只持有邮箱令牌的访客可以为自己的邮箱注册 Webhook：owner_type 为 mailbox 时 user_id 为空，owner_id 为邮箱ID，随邮箱过期一起删除
+
+ALTER TABLE `webhooks`
+    ADD COLUMN `owner_type` VARCHAR(16) DEFAULT 'user' COMMENT '归属类型：user / mailbox' AFTER `org_id`,
+    ADD COLUMN `owner_id` VARCHAR(36) NULL COMMENT '邮箱 Webhook 所属的邮箱ID' AFTER `owner_type`;
+
+CREATE INDEX `idx_webhooks_owner` ON `webhooks`(`owner_type`, `owner_id`);
@@ -0,0 +1,3 @@
+-- MySQL Rollback: HTML 转换的纯文本标记
+
+ALTER TABLE `messages` DROP COLUMN `text_derived_from_html`;
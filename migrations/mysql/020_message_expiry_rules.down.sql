@@ -0,0 +1,8 @@
+-- MySQL Rollback: 邮件到期规则
+
+ALTER TABLE `messages`
+    DROP INDEX `idx_messages_expires_at`,
+    DROP COLUMN `expires_at`;
+
+ALTER TABLE `mailboxes`
+    DROP COLUMN `expiry_rules`;
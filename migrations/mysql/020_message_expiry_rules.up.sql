@@ -0,0 +1,9 @@
+-- MySQL Migration: 邮件到期规则
+-- 邮箱可设置最多 10 条到期规则（发件人/主题正则、是否带附件、时长），入库时第一条命中的规则决定邮件的删除时间
+
+ALTER TABLE `mailboxes`
+    ADD COLUMN `expiry_rules` JSON NULL COMMENT '邮件到期规则（按顺序匹配）';
+
+ALTER TABLE `messages`
+    ADD COLUMN `expires_at` TIMESTAMP NULL COMMENT '按到期规则计算的删除时间（没有命中规则时为空）',
+    ADD INDEX `idx_messages_expires_at` (`expires_at`);
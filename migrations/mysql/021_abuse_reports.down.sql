@@ -0,0 +1,6 @@
+-- MySQL Rollback: 滥用举报
+
+ALTER TABLE `mailboxes`
+    DROP COLUMN `suspended`;
+
+DROP TABLE IF EXISTS `abuse_reports`;
@@ -0,0 +1,28 @@
+-- MySQL Migration: 滥用举报
+-- 举报人针对共享域名上的邮件提交举报，管理员分拣后隔离邮件、停用邮箱或驳回
+
+CREATE TABLE IF NOT EXISTS `abuse_reports` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '举报ID',
+    `reporter_email` VARCHAR(254) COMMENT '举报人邮箱',
+    `reporter_ip` VARCHAR(64) COMMENT '举报人IP',
+    `target` VARCHAR(512) COMMENT '举报人提交的地址或邮件链接',
+    `category` VARCHAR(20) COMMENT '类别',
+    `details` TEXT COMMENT '举报说明',
+    `status` VARCHAR(20) COMMENT '处理状态',
+    `mailbox_id` VARCHAR(36) COMMENT '关联的邮箱ID',
+    `message_id` VARCHAR(36) COMMENT '关联的邮件ID',
+    `priority` BOOLEAN DEFAULT FALSE COMMENT '同一邮箱被多个举报人举报，需优先处理',
+    `action` VARCHAR(20) COMMENT '处置方式',
+    `note` TEXT COMMENT '管理员处理备注',
+    `resolved_by` VARCHAR(36) COMMENT '处理人',
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `resolved_at` TIMESTAMP NULL COMMENT '处理时间',
+    INDEX `idx_abuse_reports_status` (`status`),
+    INDEX `idx_abuse_reports_mailbox_id` (`mailbox_id`),
+    INDEX `idx_abuse_reports_priority` (`priority`),
+    INDEX `idx_abuse_reports_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='滥用举报';
+
+ALTER TABLE `mailboxes`
+    ADD COLUMN `suspended` BOOLEAN DEFAULT FALSE COMMENT '因滥用被管理员停用（拒绝读取和投递）';
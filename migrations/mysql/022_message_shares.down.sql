@@ -0,0 +1,3 @@
+-- MySQL Rollback: 邮件分享链接
+
+DROP TABLE IF EXISTS `message_shares`;
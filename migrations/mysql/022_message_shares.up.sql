@@ -0,0 +1,18 @@
+-- MySQL Migration: 邮件分享链接
+-- 单封邮件的限时只读链接，链接为签名令牌，记录保存 nonce 用于单独撤销
+
+CREATE TABLE IF NOT EXISTS `message_shares` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '分享ID',
+    `mailbox_id` VARCHAR(36) NOT NULL COMMENT '邮箱ID',
+    `message_id` VARCHAR(36) NOT NULL COMMENT '邮件ID',
+    `nonce` VARCHAR(64) NOT NULL COMMENT '链接令牌中的随机数，撤销后不再匹配',
+    `allow_attachments` BOOLEAN DEFAULT FALSE COMMENT '是否允许下载附件',
+    `redacted` BOOLEAN DEFAULT FALSE COMMENT '展示脱敏副本而不是原文',
+    `views` BIGINT DEFAULT 0 COMMENT '访问次数',
+    `last_viewed_at` TIMESTAMP NULL COMMENT '最近访问时间',
+    `expires_at` TIMESTAMP NULL COMMENT '过期时间',
+    `revoked_at` TIMESTAMP NULL COMMENT '撤销时间',
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    INDEX `idx_message_shares_mailbox_id` (`mailbox_id`),
+    INDEX `idx_message_shares_message_id` (`message_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='邮件分享链接';
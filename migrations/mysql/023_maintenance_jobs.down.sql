@@ -0,0 +1,3 @@
+-- MySQL Rollback: 维护任务
+
+DROP TABLE IF EXISTS `maintenance_jobs`;
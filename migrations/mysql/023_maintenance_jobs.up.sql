@@ -0,0 +1,24 @@
+-- MySQL Migration: 维护任务
+-- 管理员触发的回填、重新统计等批量任务，按主键分批处理并保存游标，重启后从游标继续
+
+CREATE TABLE IF NOT EXISTS `maintenance_jobs` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '任务ID',
+    `type` VARCHAR(50) COMMENT '任务类型',
+    `params` JSON COMMENT '任务参数',
+    `status` VARCHAR(20) COMMENT '状态',
+    `cursor` VARCHAR(255) COMMENT '最近一批处理完的最后一条记录的主键',
+    `total` BIGINT DEFAULT 0 COMMENT '待扫描记录数（估算）',
+    `processed` BIGINT DEFAULT 0 COMMENT '已扫描记录数',
+    `updated` BIGINT DEFAULT 0 COMMENT '已修改记录数',
+    `error` TEXT COMMENT '失败原因',
+    `created_by` VARCHAR(36) COMMENT '创建人',
+    `active_type` VARCHAR(50) NULL COMMENT '排队或运行中时等于 type，结束后为空',
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `started_at` TIMESTAMP NULL COMMENT '开始时间',
+    `finished_at` TIMESTAMP NULL COMMENT '结束时间',
+    INDEX `idx_maintenance_jobs_type` (`type`),
+    INDEX `idx_maintenance_jobs_status` (`status`),
+    INDEX `idx_maintenance_jobs_created_at` (`created_at`),
+    UNIQUE INDEX `idx_maintenance_jobs_active_type` (`active_type`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='维护任务';
@@ -0,0 +1,3 @@
+-- MySQL Rollback: 使用情况统计
+
+DROP TABLE IF EXISTS `analytics_buckets`;
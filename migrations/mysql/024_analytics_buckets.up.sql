@@ -0,0 +1,11 @@
+-- MySQL Migration: 使用情况统计
+-- 只保存按小时/天汇总的计数；维度中不含邮箱地址、IP 等可识别个人的数据，发件人以每日轮换盐的哈希记录
+
+CREATE TABLE IF NOT EXISTS `analytics_buckets` (
+    `metric` VARCHAR(64) NOT NULL COMMENT '指标',
+    `dimension` VARCHAR(255) NOT NULL COMMENT '维度（域名、功能名或发件人哈希）',
+    `bucket` DATETIME NOT NULL COMMENT 'UTC 整点（每日指标为 UTC 零点）',
+    `value` BIGINT DEFAULT 0 COMMENT '计数类指标为累计值，峰值类指标为最大值',
+    PRIMARY KEY (`metric`, `dimension`, `bucket`),
+    INDEX `idx_analytics_buckets_bucket` (`bucket`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='使用情况统计（汇总计数）';
@@ -0,0 +1,3 @@
+-- MySQL Rollback: 已发送邮件
+
+DROP TABLE IF EXISTS `sent_messages`;
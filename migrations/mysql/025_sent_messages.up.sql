@@ -0,0 +1,20 @@
+-- MySQL Migration: 已发送邮件
+-- 邮箱经发信中继发出的邮件（“已发送”文件夹），发信配额按邮箱和用户统计最近 24 小时的记录
+
+CREATE TABLE IF NOT EXISTS `sent_messages` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '记录ID',
+    `mailbox_id` VARCHAR(36) NOT NULL COMMENT '邮箱ID',
+    `user_id` VARCHAR(36) NULL COMMENT '发信时邮箱所属用户（游客邮箱为空）',
+    `from` VARCHAR(255) COMMENT '发件人',
+    `to` JSON COMMENT '收件人',
+    `cc` JSON COMMENT '抄送',
+    `subject` VARCHAR(500) COMMENT '主题',
+    `text` TEXT COMMENT '纯文本正文',
+    `html` TEXT COMMENT 'HTML 正文',
+    `internet_message_id` VARCHAR(255) COMMENT '邮件头中的 Message-ID（不含尖括号）',
+    `reply_to_message_id` VARCHAR(36) COMMENT '回复的收件邮件ID',
+    `size` BIGINT DEFAULT 0 COMMENT '原始邮件大小（字节）',
+    `sent_at` TIMESTAMP NULL COMMENT '发送时间',
+    INDEX `idx_sent_messages_mailbox_sent` (`mailbox_id`, `sent_at`),
+    INDEX `idx_sent_messages_user_sent` (`user_id`, `sent_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='已发送邮件';
@@ -0,0 +1,6 @@
+-- MySQL Rollback: 收信 DKIM 验证结果
+
+ALTER TABLE `messages`
+    DROP INDEX `idx_messages_dkim_result`,
+    DROP COLUMN `dkim_domain`,
+    DROP COLUMN `dkim_result`;
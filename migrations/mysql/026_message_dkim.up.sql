@@ -0,0 +1,7 @@
+-- MySQL Migration: 收信 DKIM 验证结果
+-- pass/fail/none，未验证的历史邮件为空
+
+ALTER TABLE `messages`
+    ADD COLUMN `dkim_result` VARCHAR(16) COMMENT 'DKIM 验证结果：pass、fail 或 none（未验证时为空）',
+    ADD COLUMN `dkim_domain` VARCHAR(255) COMMENT '签名域名（d=）',
+    ADD INDEX `idx_messages_dkim_result` (`dkim_result`);
@@ -0,0 +1,4 @@
+-- MySQL Rollback: 收信 SPF/DMARC 认证结果
+
+ALTER TABLE `messages`
+    DROP COLUMN `auth_results`;
@@ -0,0 +1,5 @@
+-- MySQL Migration: 收信 SPF/DMARC 认证结果
+-- JSON：spf、dkim、dmarc 结果、From 域名策略及未通过时的处理方式
+
+ALTER TABLE `messages`
+    ADD COLUMN `auth_results` JSON COMMENT '发件人认证结果（SPF、DKIM、DMARC），未检查时为空';
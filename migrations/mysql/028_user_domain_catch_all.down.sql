@@ -0,0 +1,5 @@
+-- MySQL Rollback: 用户域名通配收件
+
+ALTER TABLE `user_domains`
+    DROP COLUMN `catch_all_mailbox_id`,
+    DROP COLUMN `catch_all_enabled`;
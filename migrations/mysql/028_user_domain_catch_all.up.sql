@@ -0,0 +1,6 @@
+-- MySQL Migration: 用户域名通配收件
+-- 通配模式的域名接收发往不存在地址的邮件：投递到指定邮箱，或为所有者自动创建邮箱
+
+ALTER TABLE `user_domains`
+    ADD COLUMN `catch_all_enabled` BOOLEAN DEFAULT FALSE COMMENT '通配收件（仅通配模式的已验证域名生效）',
+    ADD COLUMN `catch_all_mailbox_id` VARCHAR(36) DEFAULT NULL COMMENT '通配邮箱，为空时按收件地址自动创建邮箱';
@@ -0,0 +1,3 @@
+-- MySQL Rollback: 用户域名白名单
+
+DROP TABLE IF EXISTS `domain_whitelist_entries`;
@@ -0,0 +1,10 @@
+-- MySQL Migration: 用户域名白名单
+-- 白名单模式的域名只允许列表中的前缀创建邮箱和接收邮件
+
+CREATE TABLE IF NOT EXISTS `domain_whitelist_entries` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '条目ID',
+    `domain_id` VARCHAR(36) NOT NULL COMMENT '用户域名ID',
+    `local_part` VARCHAR(64) NOT NULL COMMENT '允许的邮箱前缀（小写）',
+    `created_at` TIMESTAMP NULL COMMENT '添加时间',
+    UNIQUE INDEX `idx_domain_whitelist_domain_local` (`domain_id`, `local_part`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户域名白名单';
@@ -0,0 +1,32 @@
+-- MySQL Migration: 邮箱转发
+-- 收到的邮件经发信中继复制一份发往已确认的外部地址，投递失败按退避重试
+
+CREATE TABLE IF NOT EXISTS `mailbox_forwards` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '转发地址ID',
+    `mailbox_id` VARCHAR(36) NOT NULL COMMENT '邮箱ID',
+    `address` VARCHAR(255) NOT NULL COMMENT '目标地址（小写）',
+    `status` VARCHAR(20) NOT NULL COMMENT '状态：pending（等待确认）、verified（已确认）',
+    `code_hash` VARCHAR(64) NULL COMMENT '确认码的 SHA-256（确认后清空）',
+    `code_sent_at` TIMESTAMP NULL COMMENT '确认码发送时间',
+    `verify_attempts` BIGINT DEFAULT 0 COMMENT '确认码已尝试次数',
+    `verified_at` TIMESTAMP NULL COMMENT '确认时间',
+    `created_at` TIMESTAMP NULL COMMENT '创建时间',
+    INDEX `idx_mailbox_forwards_mailbox_id` (`mailbox_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='邮箱转发地址';
+
+CREATE TABLE IF NOT EXISTS `forward_deliveries` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '投递ID',
+    `forward_id` VARCHAR(36) NOT NULL COMMENT '转发地址ID',
+    `mailbox_id` VARCHAR(36) NOT NULL COMMENT '邮箱ID',
+    `message_id` VARCHAR(36) NOT NULL COMMENT '邮件ID',
+    `address` VARCHAR(255) NULL COMMENT '目标地址',
+    `attempts` BIGINT DEFAULT 0 COMMENT '已投递次数',
+    `success` BOOLEAN DEFAULT FALSE COMMENT '是否投递成功',
+    `error` TEXT NULL COMMENT '最近一次失败原因',
+    `next_retry` TIMESTAMP NULL COMMENT '下次投递时间，成功或放弃后为空',
+    `created_at` TIMESTAMP NULL COMMENT '创建时间',
+    `updated_at` TIMESTAMP NULL COMMENT '更新时间',
+    INDEX `idx_forward_deliveries_forward_id` (`forward_id`),
+    INDEX `idx_forward_deliveries_mailbox_id` (`mailbox_id`),
+    INDEX `idx_forward_deliveries_next_retry` (`next_retry`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='邮件转发投递队列';
@@ -0,0 +1,4 @@
+-- MySQL Rollback: 邮箱自动续期
+
+ALTER TABLE `mailboxes`
+    DROP COLUMN `auto_renew`;
@@ -0,0 +1,3 @@
+-- MySQL Rollback: 邮件全文索引
+
+DROP TABLE IF EXISTS `message_search_documents`;
@@ -0,0 +1,12 @@
+-- MySQL Migration: 邮件全文索引
+-- 入库时把主题、发件人和正文分词后写入索引文档，查询按整词匹配词项
+-- 已有邮件需运行 cmd/reindex-search 重建索引
+
+CREATE TABLE IF NOT EXISTS `message_search_documents` (
+    `message_id` VARCHAR(36) PRIMARY KEY COMMENT '邮件ID',
+    `mailbox_id` VARCHAR(36) NULL COMMENT '邮箱ID',
+    `terms` LONGTEXT NULL COMMENT '分词后去重的小写词项，空格分隔',
+    `received_at` TIMESTAMP NULL COMMENT '接收时间',
+    INDEX `idx_message_search_documents_mailbox_id` (`mailbox_id`),
+    INDEX `idx_message_search_documents_received_at` (`received_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='邮件全文索引文档（随邮件删除）';
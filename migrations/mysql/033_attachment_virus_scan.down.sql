@@ -0,0 +1,5 @@
+-- MySQL Rollback: 附件病毒扫描
+
+ALTER TABLE `attachments`
+    DROP COLUMN `infected`,
+    DROP COLUMN `threat`;
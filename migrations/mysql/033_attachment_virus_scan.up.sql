@@ -0,0 +1,6 @@
+-- MySQL Migration: 附件病毒扫描
+-- 入库时由 clamd 扫描附件，检出威胁的邮件投递到隔离区，被标记的附件禁止下载
+
+ALTER TABLE `attachments`
+    ADD COLUMN `infected` BOOLEAN DEFAULT FALSE COMMENT '病毒扫描检出威胁（禁止下载）',
+    ADD COLUMN `threat` VARCHAR(255) COMMENT '检出的威胁名称';
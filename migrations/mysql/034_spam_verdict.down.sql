@@ -0,0 +1,9 @@
+-- MySQL Rollback: 垃圾邮件判定和用户阈值
+
+ALTER TABLE `messages`
+    DROP INDEX `idx_messages_is_spam`,
+    DROP COLUMN `is_spam`;
+
+ALTER TABLE `users`
+    DROP COLUMN `spam_reject_score`,
+    DROP COLUMN `spam_quarantine_score`;
@@ -0,0 +1,10 @@
+-- MySQL Migration: 垃圾邮件判定和用户阈值
+-- 邮件记录是否判定为垃圾邮件（可按 isSpam 筛选），用户可为名下邮箱设置默认阈值
+
+ALTER TABLE `messages`
+    ADD COLUMN `is_spam` BOOLEAN DEFAULT FALSE COMMENT '评分超过收件人的软阈值（垃圾邮件判定）',
+    ADD INDEX `idx_messages_is_spam` (`is_spam`);
+
+ALTER TABLE `users`
+    ADD COLUMN `spam_quarantine_score` DOUBLE NULL COMMENT '用户自定义软阈值（为空时使用系统配置）',
+    ADD COLUMN `spam_reject_score` DOUBLE NULL COMMENT '用户自定义硬阈值（为空时使用系统配置）';
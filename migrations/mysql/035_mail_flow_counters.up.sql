@@ -0,0 +1,11 @@
+-- MySQL Migration: 收信流量统计
+-- SMTP 后端按分钟汇总接收邮件数、拒收次数（按原因）和发件/收件域名，管理后台据此查看流量突增
+
+CREATE TABLE IF NOT EXISTS `mail_flow_counters` (
+    `kind` VARCHAR(32) NOT NULL COMMENT 'accepted、rejected、sender_domain 或 recipient_domain',
+    `dimension` VARCHAR(255) NOT NULL COMMENT '拒绝原因或域名（accepted 为空）',
+    `bucket` DATETIME NOT NULL COMMENT 'UTC 整分钟',
+    `count` BIGINT DEFAULT 0 COMMENT '计数',
+    PRIMARY KEY (`kind`, `dimension`, `bucket`),
+    INDEX `idx_mail_flow_counters_bucket` (`bucket`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='收信流量计数（按分钟）';
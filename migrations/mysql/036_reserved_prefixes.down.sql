@@ -0,0 +1,3 @@
+-- MySQL Rollback: 系统域名保留前缀
+
+DROP TABLE IF EXISTS `reserved_prefixes`;
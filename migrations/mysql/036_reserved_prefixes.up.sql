@@ -0,0 +1,14 @@
+-- MySQL Migration: 系统域名保留前缀
+-- 管理员屏蔽的前缀（user_id 为空）任何人都不能创建；付费用户预留的靓号前缀在有效期内只有本人可以创建
+
+CREATE TABLE IF NOT EXISTS `reserved_prefixes` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '条目ID',
+    `domain` VARCHAR(255) NOT NULL COMMENT '小写域名，空字符串表示所有系统域名',
+    `local_part` VARCHAR(64) NOT NULL COMMENT '保留的邮箱前缀（小写）',
+    `user_id` VARCHAR(36) NULL COMMENT '预留给该用户，为空表示禁止使用',
+    `reason` VARCHAR(255) NULL COMMENT '说明',
+    `expires_at` TIMESTAMP NULL COMMENT '过期时间，为空表示永久',
+    `created_at` TIMESTAMP NULL COMMENT '添加时间',
+    UNIQUE INDEX `idx_reserved_prefixes_domain_local` (`domain`, `local_part`),
+    INDEX `idx_reserved_prefixes_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='系统域名保留前缀';
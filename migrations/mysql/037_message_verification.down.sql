@@ -0,0 +1,4 @@
+-- MySQL Rollback: 邮件验证码提取
+
+ALTER TABLE `messages`
+    DROP COLUMN `verification`;
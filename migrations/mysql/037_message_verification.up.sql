@@ -0,0 +1,5 @@
+-- MySQL Migration: 邮件验证码提取
+-- 入库时从正文提取的一次性验证码和验证链接（JSON：code、codes、links）
+
+ALTER TABLE `messages`
+    ADD COLUMN `verification` JSON NULL COMMENT '入库时提取的验证码和验证链接';
@@ -0,0 +1,5 @@
+-- MySQL Rollback: Webhook 回调内容格式
+
+ALTER TABLE `webhooks`
+    DROP COLUMN `payload_template`,
+    DROP COLUMN `payload_format`;
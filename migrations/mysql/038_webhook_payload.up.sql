@@ -0,0 +1,6 @@
+-- MySQL Migration: Webhook 回调内容格式
+-- headers（默认，事件元数据和邮件头字段）、full（另含正文、附件列表和验证码）或 custom（Go 模板生成的 JSON）
+
+ALTER TABLE `webhooks`
+    ADD COLUMN `payload_format` VARCHAR(16) DEFAULT 'headers' COMMENT '回调内容格式（headers / full / custom）',
+    ADD COLUMN `payload_template` TEXT NULL COMMENT 'custom 格式的 Go 模板，输出 JSON';
@@ -0,0 +1,6 @@
+-- MySQL Rollback: Webhook 死信
+
+ALTER TABLE `webhook_deliveries`
+    DROP INDEX `idx_webhook_deliveries_dead_letter`,
+    DROP COLUMN `redelivered_at`,
+    DROP COLUMN `dead_letter`;
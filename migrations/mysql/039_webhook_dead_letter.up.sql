@@ -0,0 +1,7 @@
+-- MySQL Migration: Webhook 死信
+-- 重试用尽的投递标记为死信，可通过 /v1/webhooks/:id/deliveries/:deliveryId/redeliver 人工重新投递
+
+ALTER TABLE `webhook_deliveries`
+    ADD COLUMN `dead_letter` BOOLEAN DEFAULT FALSE COMMENT '重试用尽，等待人工重新投递',
+    ADD COLUMN `redelivered_at` TIMESTAMP NULL COMMENT '人工重新投递的时间（之后不再列入死信）',
+    ADD INDEX `idx_webhook_deliveries_dead_letter` (`dead_letter`);
@@ -0,0 +1,14 @@
+-- MySQL Migration: 第三方登录身份
+-- 用户通过 Google、GitHub 或自定义 OIDC IdP 登录时关联的外部账号；同一提供方的账号只能关联一个用户
+
+CREATE TABLE IF NOT EXISTS `oauth_identities` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '身份ID',
+    `user_id` VARCHAR(36) NOT NULL COMMENT '关联的用户ID',
+    `provider` VARCHAR(50) NOT NULL COMMENT '提供方名称：google、github 或自定义 OIDC 名称',
+    `subject` VARCHAR(255) NOT NULL COMMENT '提供方的用户唯一标识（OIDC sub、GitHub 用户 ID）',
+    `email` VARCHAR(255) NULL COMMENT '关联时提供方返回的邮箱',
+    `created_at` TIMESTAMP NULL COMMENT '关联时间',
+    `last_login_at` TIMESTAMP NULL COMMENT '最近登录时间',
+    UNIQUE INDEX `idx_oauth_identities_provider_subject` (`provider`, `subject`),
+    INDEX `idx_oauth_identities_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='第三方登录身份';
@@ -0,0 +1,7 @@
+-- MySQL Rollback: 两步验证（TOTP）
+
+ALTER TABLE `users`
+    DROP COLUMN `totp_secret`,
+    DROP COLUMN `totp_enabled`,
+    DROP COLUMN `totp_last_step`,
+    DROP COLUMN `recovery_codes`;
@@ -0,0 +1,8 @@
+-- MySQL Migration: 两步验证（TOTP）
+-- 密钥加密保存，设置后验证通过才启用；恢复码只保存 SHA-256 摘要
+
+ALTER TABLE `users`
+    ADD COLUMN `totp_secret` VARCHAR(255) COMMENT 'TOTP 密钥（AES-GCM 加密）',
+    ADD COLUMN `totp_enabled` BOOLEAN DEFAULT FALSE COMMENT '是否已启用两步验证',
+    ADD COLUMN `totp_last_step` BIGINT DEFAULT 0 COMMENT '最近一次使用的验证码时间步（防重放）',
+    ADD COLUMN `recovery_codes` JSON COMMENT '未使用的恢复码摘要';
@@ -0,0 +1,3 @@
+-- MySQL Rollback: 登录会话
+
+DROP TABLE IF EXISTS `user_sessions`;
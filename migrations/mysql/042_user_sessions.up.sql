@@ -0,0 +1,17 @@
+-- MySQL Migration: 登录会话
+-- 每次登录一个会话；刷新时轮换令牌并记录当前刷新令牌 jti，重放旧刷新令牌时整个会话作废
+
+CREATE TABLE IF NOT EXISTS `user_sessions` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '会话ID（令牌 sid 声明）',
+    `user_id` VARCHAR(36) NOT NULL COMMENT '用户ID',
+    `refresh_token_id` VARCHAR(36) NOT NULL COMMENT '当前有效的刷新令牌 jti',
+    `access_token_id` VARCHAR(36) NULL COMMENT '最近签发的访问令牌 jti（撤销会话时加入黑名单）',
+    `access_expires_at` TIMESTAMP NULL COMMENT '最近签发的访问令牌过期时间',
+    `ip` VARCHAR(45) NULL COMMENT '登录或最近刷新的 IP',
+    `user_agent` VARCHAR(512) NULL COMMENT '登录或最近刷新的 User-Agent',
+    `created_at` TIMESTAMP NULL COMMENT '登录时间',
+    `last_used_at` TIMESTAMP NULL COMMENT '最近刷新时间',
+    `expires_at` TIMESTAMP NULL COMMENT '刷新令牌过期时间',
+    INDEX `idx_user_sessions_user_id` (`user_id`),
+    INDEX `idx_user_sessions_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='登录会话';
@@ -0,0 +1,19 @@
+-- MySQL Migration: 管理操作审计记录
+-- 每个成功的管理写操作一条记录（操作者、操作、目标、字段变更），只追加
+
+CREATE TABLE IF NOT EXISTS `audit_logs` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '记录ID',
+    `actor_id` VARCHAR(36) NULL COMMENT '操作者用户ID',
+    `actor_email` VARCHAR(255) NULL COMMENT '操作者邮箱',
+    `action` VARCHAR(128) NULL COMMENT '操作，如 user.update、domain.delete、config.update',
+    `target_type` VARCHAR(32) NULL COMMENT '目标类型',
+    `target_id` VARCHAR(255) NULL COMMENT '目标ID',
+    `changes` JSON NULL COMMENT '字段变更列表（field、from、to）',
+    `note` VARCHAR(512) NULL COMMENT '附加说明（如代为登录的原因）',
+    `ip` VARCHAR(45) NULL COMMENT '操作者 IP',
+    `created_at` TIMESTAMP NULL COMMENT '操作时间',
+    INDEX `idx_audit_logs_actor_id` (`actor_id`),
+    INDEX `idx_audit_logs_action` (`action`),
+    INDEX `idx_audit_logs_target` (`target_type`, `target_id`),
+    INDEX `idx_audit_logs_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='管理操作审计记录';
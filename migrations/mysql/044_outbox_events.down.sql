@@ -0,0 +1,3 @@
+-- MySQL Rollback: 事务性发件箱
+
+DROP TABLE IF EXISTS `outbox_events`;
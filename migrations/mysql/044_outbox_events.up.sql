@@ -0,0 +1,20 @@
+-- MySQL Migration: 事务性发件箱
+-- 新邮件事件与邮件在同一事务写入，由分发器投递到 WebSocket、Webhook 等目标后标记为已处理
+
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+    `id` VARCHAR(36) PRIMARY KEY COMMENT '事件ID',
+    `type` VARCHAR(64) NULL COMMENT '事件类型，如 mail.received',
+    `mailbox_id` VARCHAR(36) NOT NULL COMMENT '邮箱ID',
+    `message_id` VARCHAR(36) NOT NULL COMMENT '邮件ID',
+    `delivered` JSON NULL COMMENT '已完成的投递目标，重试时跳过',
+    `attempts` BIGINT DEFAULT 0 COMMENT '失败次数',
+    `last_error` TEXT NULL COMMENT '最近一次失败原因',
+    `claim_token` VARCHAR(36) NULL COMMENT '最近一次认领的令牌',
+    `claimed_until` TIMESTAMP NULL COMMENT '认领到期时间，之前其他分发器不会取到',
+    `next_attempt_at` TIMESTAMP NULL COMMENT '下次投递时间',
+    `processed_at` TIMESTAMP NULL COMMENT '全部目标投递完成（或放弃）的时间',
+    `created_at` TIMESTAMP NULL COMMENT '创建时间',
+    INDEX `idx_outbox_events_claim_token` (`claim_token`),
+    INDEX `idx_outbox_events_next_attempt_at` (`next_attempt_at`),
+    INDEX `idx_outbox_events_processed_at` (`processed_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='事务性发件箱事件';
@@ -0,0 +1,4 @@
+-- MySQL Rollback: 应用专用密码代数
+
+ALTER TABLE `users`
+    DROP COLUMN `app_password_generation`;
@@ -0,0 +1,5 @@
+-- MySQL Migration: 应用专用密码代数
+-- 参与派生应用密码，重置应用密码、修改密码、注销全部会话时递增，旧应用密码随之失效
+
+ALTER TABLE `users`
+    ADD COLUMN `app_password_generation` BIGINT DEFAULT 0 COMMENT '应用专用密码代数';
@@ -0,0 +1,6 @@
+-- PostgreSQL Rollback: 收件统计
+
+DROP INDEX IF EXISTS idx_messages_mailbox_received;
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS size;
@@ -0,0 +1,12 @@
+-- PostgreSQL Migration: 收件统计
+-- 邮件大小字段与 (mailbox_id, received_at) 复合索引，供按小时聚合使用
+
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS received_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS size BIGINT DEFAULT 0;
+
+UPDATE messages SET received_at = created_at WHERE received_at IS NULL;
+
+COMMENT ON COLUMN messages.size IS '邮件大小（字节）';
+
+CREATE INDEX IF NOT EXISTS idx_messages_mailbox_received ON messages(mailbox_id, received_at);
@@ -0,0 +1,17 @@
+-- PostgreSQL Rollback: 组织/团队
+
+DROP INDEX IF EXISTS idx_webhooks_org_id;
+ALTER TABLE IF EXISTS webhooks DROP COLUMN IF EXISTS org_id;
+
+DROP INDEX IF EXISTS idx_tags_org_id;
+ALTER TABLE tags DROP COLUMN IF EXISTS org_id;
+
+DROP INDEX IF EXISTS idx_user_domains_org_id;
+ALTER TABLE user_domains DROP COLUMN IF EXISTS org_id;
+
+DROP INDEX IF EXISTS idx_mailboxes_org_id;
+ALTER TABLE mailboxes DROP COLUMN IF EXISTS org_id;
+
+DROP TABLE IF EXISTS org_invites;
+DROP TABLE IF EXISTS org_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,65 @@
+-- PostgreSQL Migration: 组织/团队
+-- 组织、成员、邀请表，以及邮箱/用户域名/Webhook/标签的可选组织归属
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    owner_id VARCHAR(36) NOT NULL,
+    tier VARCHAR(20) DEFAULT 'free',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_organizations_owner_id ON organizations(owner_id);
+
+COMMENT ON TABLE organizations IS '组织表';
+COMMENT ON COLUMN organizations.tier IS '组织等级（决定组织配额）: free/basic/pro/enterprise';
+
+CREATE TABLE IF NOT EXISTS org_members (
+    org_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    role VARCHAR(20) DEFAULT 'member',
+    joined_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, user_id),
+    FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_members_user_id ON org_members(user_id);
+
+COMMENT ON TABLE org_members IS '组织成员表';
+COMMENT ON COLUMN org_members.role IS '角色: owner/member';
+
+CREATE TABLE IF NOT EXISTS org_invites (
+    token VARCHAR(64) PRIMARY KEY,
+    org_id VARCHAR(36) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    invited_by VARCHAR(36),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_invites_org_id ON org_invites(org_id);
+
+COMMENT ON TABLE org_invites IS '组织邀请表（加入令牌一次性使用）';
+
+-- 资源的组织归属
+ALTER TABLE mailboxes ADD COLUMN IF NOT EXISTS org_id VARCHAR(36);
+CREATE INDEX IF NOT EXISTS idx_mailboxes_org_id ON mailboxes(org_id);
+
+ALTER TABLE user_domains ADD COLUMN IF NOT EXISTS org_id VARCHAR(36);
+CREATE INDEX IF NOT EXISTS idx_user_domains_org_id ON user_domains(org_id);
+
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS org_id VARCHAR(36);
+CREATE INDEX IF NOT EXISTS idx_tags_org_id ON tags(org_id);
+
+-- webhooks 表由 GORM 创建，可能尚不存在
+DO $$
+BEGIN
+    IF to_regclass('webhooks') IS NOT NULL THEN
+        ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS org_id VARCHAR(36);
+        CREATE INDEX IF NOT EXISTS idx_webhooks_org_id ON webhooks(org_id);
+    END IF;
+END $$;
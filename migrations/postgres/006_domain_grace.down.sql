@@ -0,0 +1,4 @@
+-- PostgreSQL Rollback: 用户域名过期宽限期
+
+ALTER TABLE user_domains
+    DROP COLUMN IF EXISTS grace_notices;
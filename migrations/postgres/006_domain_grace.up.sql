@@ -0,0 +1,6 @@
+-- PostgreSQL Migration: 用户域名过期宽限期
+-- 记录宽限期内已发送的过期提醒次数，续期后清零
+
+ALTER TABLE user_domains ADD COLUMN IF NOT EXISTS grace_notices INTEGER DEFAULT 0;
+
+COMMENT ON COLUMN user_domains.grace_notices IS '宽限期内已发送的过期提醒次数';
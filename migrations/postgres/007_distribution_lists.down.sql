@@ -0,0 +1,4 @@
+-- PostgreSQL Rollback: 分发列表
+
+DROP TABLE IF EXISTS distribution_list_deliveries;
+DROP TABLE IF EXISTS distribution_lists;
@@ -0,0 +1,37 @@
+-- PostgreSQL Migration: 分发列表
+-- 发往列表地址的邮件复制到每个成员邮箱，投递报告记录每个成员的结果
+
+CREATE TABLE IF NOT EXISTS distribution_lists (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    org_id VARCHAR(36),
+    name VARCHAR(100),
+    address VARCHAR(255) NOT NULL UNIQUE,
+    members JSON,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_distribution_lists_user_id ON distribution_lists(user_id);
+CREATE INDEX IF NOT EXISTS idx_distribution_lists_org_id ON distribution_lists(org_id);
+
+COMMENT ON TABLE distribution_lists IS '分发列表';
+COMMENT ON COLUMN distribution_lists.members IS '成员邮箱ID或嵌套列表ID（有序 JSON 数组）';
+
+CREATE TABLE IF NOT EXISTS distribution_list_deliveries (
+    id VARCHAR(36) PRIMARY KEY,
+    list_id VARCHAR(36) NOT NULL,
+    "from" VARCHAR(255),
+    subject VARCHAR(500),
+    delivered JSON,
+    failures JSON,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (list_id) REFERENCES distribution_lists(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_distribution_list_deliveries_list_id ON distribution_list_deliveries(list_id);
+CREATE INDEX IF NOT EXISTS idx_distribution_list_deliveries_created_at ON distribution_list_deliveries(created_at);
+
+COMMENT ON TABLE distribution_list_deliveries IS '分发列表投递报告';
@@ -0,0 +1,12 @@
+-- PostgreSQL Rollback: 垃圾邮件评分
+
+DROP INDEX IF EXISTS idx_messages_quarantined;
+DROP INDEX IF EXISTS idx_messages_spam_score;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS quarantined;
+ALTER TABLE messages DROP COLUMN IF EXISTS spam_symbols;
+ALTER TABLE messages DROP COLUMN IF EXISTS spam_action;
+ALTER TABLE messages DROP COLUMN IF EXISTS spam_score;
+
+ALTER TABLE mailboxes DROP COLUMN IF EXISTS spam_reject_score;
+ALTER TABLE mailboxes DROP COLUMN IF EXISTS spam_quarantine_score;
@@ -0,0 +1,18 @@
+-- PostgreSQL Migration: 垃圾邮件评分
+-- 邮件记录评分、建议动作和命中规则，超过软阈值的邮件进入隔离区；邮箱可自定义阈值
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS spam_score DOUBLE PRECISION DEFAULT 0;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS spam_action VARCHAR(32);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS spam_symbols JSON;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS quarantined BOOLEAN DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_messages_spam_score ON messages(spam_score);
+CREATE INDEX IF NOT EXISTS idx_messages_quarantined ON messages(quarantined);
+
+ALTER TABLE mailboxes ADD COLUMN IF NOT EXISTS spam_quarantine_score DOUBLE PRECISION;
+ALTER TABLE mailboxes ADD COLUMN IF NOT EXISTS spam_reject_score DOUBLE PRECISION;
+
+COMMENT ON COLUMN messages.spam_score IS '垃圾邮件评分（未评分时为 0）';
+COMMENT ON COLUMN messages.quarantined IS '超过软阈值，投递到隔离区';
+COMMENT ON COLUMN mailboxes.spam_quarantine_score IS '自定义软阈值（为空时使用系统配置）';
+COMMENT ON COLUMN mailboxes.spam_reject_score IS '自定义硬阈值（为空时使用系统配置）';
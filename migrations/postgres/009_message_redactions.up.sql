@@ -0,0 +1,22 @@
+-- PostgreSQL Migration: 邮件脱敏副本
+-- 分享邮件前生成的脱敏副本，每封邮件一份，记录使用的规则和规则版本
+
+CREATE TABLE IF NOT EXISTS message_redactions (
+    message_id VARCHAR(36) PRIMARY KEY,
+    mailbox_id VARCHAR(36) NOT NULL,
+    rules_version INTEGER NOT NULL DEFAULT 1,
+    rules JSON,
+    patterns JSON,
+    subject VARCHAR(500),
+    "from" VARCHAR(255),
+    text TEXT,
+    html TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_redactions_mailbox_id ON message_redactions(mailbox_id);
+
+COMMENT ON TABLE message_redactions IS '邮件脱敏副本';
+COMMENT ON COLUMN message_redactions.rules_version IS '生成时的内置规则版本';
+COMMENT ON COLUMN message_redactions.patterns IS '自定义正则（JSON 数组）';
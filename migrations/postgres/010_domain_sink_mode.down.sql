@@ -0,0 +1,11 @@
+-- PostgreSQL Rollback: 域名黑洞模式
+
+ALTER TABLE system_domains
+    DROP COLUMN IF EXISTS sink_enabled,
+    DROP COLUMN IF EXISTS sink_sample_rate,
+    DROP COLUMN IF EXISTS sink_diagnostics_mailbox_id;
+
+ALTER TABLE user_domains
+    DROP COLUMN IF EXISTS sink_enabled,
+    DROP COLUMN IF EXISTS sink_sample_rate,
+    DROP COLUMN IF EXISTS sink_diagnostics_mailbox_id;
@@ -0,0 +1,15 @@
+-- PostgreSQL Migration: 域名黑洞模式
+-- 开启后接收域名下任意收件人，邮件不保存，只在 Redis 中累计汇总统计
+
+ALTER TABLE system_domains ADD COLUMN IF NOT EXISTS sink_enabled BOOLEAN DEFAULT FALSE;
+ALTER TABLE system_domains ADD COLUMN IF NOT EXISTS sink_sample_rate INTEGER DEFAULT 0;
+ALTER TABLE system_domains ADD COLUMN IF NOT EXISTS sink_diagnostics_mailbox_id VARCHAR(36);
+
+ALTER TABLE user_domains ADD COLUMN IF NOT EXISTS sink_enabled BOOLEAN DEFAULT FALSE;
+ALTER TABLE user_domains ADD COLUMN IF NOT EXISTS sink_sample_rate INTEGER DEFAULT 0;
+ALTER TABLE user_domains ADD COLUMN IF NOT EXISTS sink_diagnostics_mailbox_id VARCHAR(36);
+
+COMMENT ON COLUMN system_domains.sink_enabled IS '黑洞模式：接收任意收件人，不保存邮件';
+COMMENT ON COLUMN system_domains.sink_sample_rate IS '每 N 封抽样 1 封投递到诊断邮箱，0 表示不抽样';
+COMMENT ON COLUMN user_domains.sink_enabled IS '黑洞模式（仅独享模式的已验证域名生效）';
+COMMENT ON COLUMN user_domains.sink_sample_rate IS '每 N 封抽样 1 封投递到诊断邮箱，0 表示不抽样';
@@ -0,0 +1,9 @@
+-- PostgreSQL Rollback: 闲置邮箱检测
+
+DROP INDEX IF EXISTS idx_mailboxes_last_accessed_at;
+
+ALTER TABLE mailboxes
+    DROP COLUMN IF EXISTS last_accessed_at,
+    DROP COLUMN IF EXISTS idle_since,
+    DROP COLUMN IF EXISTS idle_shortened,
+    DROP COLUMN IF EXISTS idle_original_expires_at;
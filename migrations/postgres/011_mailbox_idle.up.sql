@@ -0,0 +1,14 @@
+-- PostgreSQL Migration: 闲置邮箱检测
+-- 记录邮箱最近一次被访问的时间（API 读取、WebSocket 订阅），SMTP 投递不算访问
+
+ALTER TABLE mailboxes ADD COLUMN IF NOT EXISTS last_accessed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE mailboxes ADD COLUMN IF NOT EXISTS idle_since TIMESTAMP WITH TIME ZONE;
+ALTER TABLE mailboxes ADD COLUMN IF NOT EXISTS idle_shortened BOOLEAN DEFAULT FALSE;
+ALTER TABLE mailboxes ADD COLUMN IF NOT EXISTS idle_original_expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_mailboxes_last_accessed_at ON mailboxes(last_accessed_at);
+
+COMMENT ON COLUMN mailboxes.last_accessed_at IS '最近一次被访问的时间，每小时最多更新一次';
+COMMENT ON COLUMN mailboxes.idle_since IS '被判定为闲置并已提醒的时间，再次访问后清空';
+COMMENT ON COLUMN mailboxes.idle_shortened IS '闲置时是否缩短了有效期';
+COMMENT ON COLUMN mailboxes.idle_original_expires_at IS '缩短前的过期时间，再次访问时恢复';
@@ -0,0 +1,6 @@
+-- PostgreSQL Rollback: 公开收件箱
+
+DROP INDEX IF EXISTS idx_mailboxes_is_public;
+
+ALTER TABLE mailboxes DROP COLUMN IF EXISTS is_public;
+ALTER TABLE system_domains DROP COLUMN IF EXISTS allow_public_inboxes;
@@ -0,0 +1,10 @@
+-- PostgreSQL Migration: 公开收件箱
+-- 公开收件箱无需令牌即可只读查看，邮件按固定短时限自动删除
+
+ALTER TABLE mailboxes ADD COLUMN IF NOT EXISTS is_public BOOLEAN DEFAULT FALSE;
+ALTER TABLE system_domains ADD COLUMN IF NOT EXISTS allow_public_inboxes BOOLEAN DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_mailboxes_is_public ON mailboxes(is_public);
+
+COMMENT ON COLUMN mailboxes.is_public IS '是否为公开收件箱（管理员设置，或在允许的域名上创建时指定）';
+COMMENT ON COLUMN system_domains.allow_public_inboxes IS '是否允许创建邮箱时直接设为公开收件箱';
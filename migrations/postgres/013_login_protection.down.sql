@@ -0,0 +1,4 @@
+-- PostgreSQL Rollback: 登录防暴力破解
+
+ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE users DROP COLUMN IF EXISTS recent_login_ips;
@@ -0,0 +1,8 @@
+-- PostgreSQL Migration: 登录防暴力破解
+-- 连续登录失败锁定账户；记录最近登录 IP，新 IP 登录时提醒用户
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS recent_login_ips JSONB;
+
+COMMENT ON COLUMN users.locked_until IS '连续登录失败后锁定至该时间（为空表示未锁定）';
+COMMENT ON COLUMN users.recent_login_ips IS '最近成功登录的 IP 列表（最多保留 10 个）';
@@ -0,0 +1,4 @@
+-- PostgreSQL Rollback: 附件实际类型判断
+
+ALTER TABLE attachments DROP COLUMN IF EXISTS detected_content_type;
+ALTER TABLE attachments DROP COLUMN IF EXISTS content_type_mismatch;
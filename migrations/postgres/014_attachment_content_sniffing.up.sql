@@ -0,0 +1,9 @@
+-- PostgreSQL Migration: 附件实际类型判断
+-- 入库时按内容魔数判断附件实际类型，下载按实际类型返回；声明类型与实际类型不一致时标记提示
+
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS detected_content_type VARCHAR(100);
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS content_type_mismatch BOOLEAN DEFAULT FALSE;
+
+COMMENT ON COLUMN attachments.content_type IS '发件方声明的 MIME 类型';
+COMMENT ON COLUMN attachments.detected_content_type IS '按内容魔数判断的实际类型';
+COMMENT ON COLUMN attachments.content_type_mismatch IS '声明类型与实际类型存在安全相关的不一致（如伪装成图片的可执行文件）';
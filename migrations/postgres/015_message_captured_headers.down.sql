@@ -0,0 +1,4 @@
+-- PostgreSQL Rollback: 邮件自定义头
+
+DROP INDEX IF EXISTS idx_messages_captured_headers;
+ALTER TABLE messages DROP COLUMN IF EXISTS captured_headers;
@@ -0,0 +1,8 @@
+-- PostgreSQL Migration: 邮件自定义头
+-- 收信时按名单（mailbox.captured_headers / 系统配置）保存 X-Test-Run-ID 等头，邮件列表和搜索可按 header.<名称>=<值> 精确过滤
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS captured_headers JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_messages_captured_headers ON messages USING GIN (captured_headers jsonb_path_ops);
+
+COMMENT ON COLUMN messages.captured_headers IS '收信时按名单保存的头（键为规范化头名）';
@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: Webhook 投递失败类型
+
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS error_kind;
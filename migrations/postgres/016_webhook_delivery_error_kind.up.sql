@@ -0,0 +1,6 @@
+-- PostgreSQL Migration: Webhook 投递失败类型
+-- 区分网络错误、HTTP 状态码错误和熔断短路（circuit_open），短路投递不计入失败次数
+
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS error_kind VARCHAR(32);
+
+COMMENT ON COLUMN webhook_deliveries.error_kind IS '失败类型：network / http_status / circuit_open';
@@ -0,0 +1,5 @@
+-- PostgreSQL Rollback: 邮件入库序号
+
+DROP INDEX IF EXISTS idx_messages_mailbox_seq;
+DROP TABLE IF EXISTS message_sequences;
+ALTER TABLE messages DROP COLUMN IF EXISTS seq;
@@ -0,0 +1,27 @@
+-- PostgreSQL Migration: 邮件入库序号
+-- 每个邮箱内单调递增的序号，在保存邮件的事务内分配；列表和搜索按序号排序，WebSocket 通知携带序号供客户端检测遗漏
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS seq BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS message_sequences (
+    mailbox_id VARCHAR(36) PRIMARY KEY,
+    last_seq BIGINT NOT NULL DEFAULT 0
+);
+
+-- 已有邮件按创建时间补齐序号
+UPDATE messages m
+SET seq = ordered.rn
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY mailbox_id ORDER BY created_at, id) AS rn
+    FROM messages
+) ordered
+WHERE m.id = ordered.id AND m.seq = 0;
+
+INSERT INTO message_sequences (mailbox_id, last_seq)
+SELECT mailbox_id, MAX(seq) FROM messages GROUP BY mailbox_id
+ON CONFLICT (mailbox_id) DO UPDATE SET last_seq = GREATEST(message_sequences.last_seq, EXCLUDED.last_seq);
+
+CREATE INDEX IF NOT EXISTS idx_messages_mailbox_seq ON messages(mailbox_id, seq);
+
+COMMENT ON COLUMN messages.seq IS '邮箱内单调递增的入库序号';
+COMMENT ON TABLE message_sequences IS '邮箱最近分配的邮件序号';
@@ -0,0 +1,7 @@
+-- PostgreSQL Rollback: 邮箱 Webhook
+
+DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE owner_type = 'mailbox');
+DELETE FROM webhooks WHERE owner_type = 'mailbox';
+DROP INDEX IF EXISTS idx_webhooks_owner;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS owner_type;
@@ -0,0 +1,10 @@
+-- PostgreSQL Migration: 邮箱 Webhook
+-- 只持有邮箱令牌的访客可以为自己的邮箱注册 Webhook：owner_type 为 mailbox 时 user_id 为空，owner_id 为邮箱ID，随邮箱过期一起删除
+
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS owner_type VARCHAR(16) DEFAULT 'user';
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS owner_id VARCHAR(36);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_owner ON webhooks(owner_type, owner_id);
+
+COMMENT ON COLUMN webhooks.owner_type IS '归属类型：user / mailbox';
+COMMENT ON COLUMN webhooks.owner_id IS '邮箱 Webhook 所属的邮箱ID';
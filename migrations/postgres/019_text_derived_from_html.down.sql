@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: HTML 转换的纯文本标记
+
+ALTER TABLE messages DROP COLUMN IF EXISTS text_derived_from_html;
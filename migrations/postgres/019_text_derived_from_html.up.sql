@@ -0,0 +1,6 @@
+-- PostgreSQL Migration: HTML 转换的纯文本标记
+-- 只有 HTML 正文的邮件在入库时由 HTML 生成纯文本，text_derived_from_html 标记该文本是合成的
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS text_derived_from_html BOOLEAN DEFAULT FALSE;
+
+COMMENT ON COLUMN messages.text_derived_from_html IS '纯文本由 HTML 转换生成（原邮件没有 text/plain 部分）';
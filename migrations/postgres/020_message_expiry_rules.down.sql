@@ -0,0 +1,5 @@
+-- PostgreSQL Rollback: 邮件到期规则
+
+DROP INDEX IF EXISTS idx_messages_expires_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE mailboxes DROP COLUMN IF EXISTS expiry_rules;
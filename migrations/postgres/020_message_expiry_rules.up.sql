@@ -0,0 +1,11 @@
+-- PostgreSQL Migration: 邮件到期规则
+-- 邮箱可设置最多 10 条到期规则（发件人/主题正则、是否带附件、时长），入库时第一条命中的规则决定邮件的删除时间
+
+ALTER TABLE mailboxes ADD COLUMN IF NOT EXISTS expiry_rules JSON;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+-- 定时清理按删除时间扫描
+CREATE INDEX IF NOT EXISTS idx_messages_expires_at ON messages(expires_at);
+
+COMMENT ON COLUMN mailboxes.expiry_rules IS '邮件到期规则（按顺序匹配）';
+COMMENT ON COLUMN messages.expires_at IS '按到期规则计算的删除时间（没有命中规则时为空）';
@@ -0,0 +1,5 @@
+-- PostgreSQL Rollback: 滥用举报
+
+ALTER TABLE mailboxes DROP COLUMN IF EXISTS suspended;
+
+DROP TABLE IF EXISTS abuse_reports;
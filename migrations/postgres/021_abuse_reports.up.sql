@@ -0,0 +1,33 @@
+-- PostgreSQL Migration: 滥用举报
+-- 举报人针对共享域名上的邮件提交举报，管理员分拣后隔离邮件、停用邮箱或驳回
+
+CREATE TABLE IF NOT EXISTS abuse_reports (
+    id VARCHAR(36) PRIMARY KEY,
+    reporter_email VARCHAR(254),
+    reporter_ip VARCHAR(64),
+    target VARCHAR(512),
+    category VARCHAR(20),
+    details TEXT,
+    status VARCHAR(20),
+    mailbox_id VARCHAR(36),
+    message_id VARCHAR(36),
+    priority BOOLEAN DEFAULT FALSE,
+    action VARCHAR(20),
+    note TEXT,
+    resolved_by VARCHAR(36),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_abuse_reports_status ON abuse_reports(status);
+CREATE INDEX IF NOT EXISTS idx_abuse_reports_mailbox_id ON abuse_reports(mailbox_id);
+CREATE INDEX IF NOT EXISTS idx_abuse_reports_priority ON abuse_reports(priority);
+CREATE INDEX IF NOT EXISTS idx_abuse_reports_created_at ON abuse_reports(created_at);
+
+ALTER TABLE mailboxes ADD COLUMN IF NOT EXISTS suspended BOOLEAN DEFAULT FALSE;
+
+COMMENT ON TABLE abuse_reports IS '滥用举报';
+COMMENT ON COLUMN abuse_reports.target IS '举报人提交的地址或邮件链接';
+COMMENT ON COLUMN abuse_reports.priority IS '同一邮箱被多个举报人举报，需优先处理';
+COMMENT ON COLUMN mailboxes.suspended IS '因滥用被管理员停用（拒绝读取和投递）';
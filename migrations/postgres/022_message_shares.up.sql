@@ -0,0 +1,23 @@
+-- PostgreSQL Migration: 邮件分享链接
+-- 单封邮件的限时只读链接，链接为签名令牌，记录保存 nonce 用于单独撤销
+
+CREATE TABLE IF NOT EXISTS message_shares (
+    id VARCHAR(36) PRIMARY KEY,
+    mailbox_id VARCHAR(36) NOT NULL,
+    message_id VARCHAR(36) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    allow_attachments BOOLEAN DEFAULT FALSE,
+    redacted BOOLEAN DEFAULT FALSE,
+    views BIGINT DEFAULT 0,
+    last_viewed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_shares_mailbox_id ON message_shares(mailbox_id);
+CREATE INDEX IF NOT EXISTS idx_message_shares_message_id ON message_shares(message_id);
+
+COMMENT ON TABLE message_shares IS '邮件分享链接';
+COMMENT ON COLUMN message_shares.nonce IS '链接令牌中的随机数，撤销后不再匹配';
+COMMENT ON COLUMN message_shares.redacted IS '展示脱敏副本而不是原文';
@@ -0,0 +1,29 @@
+-- PostgreSQL Migration: 维护任务
+-- 管理员触发的回填、重新统计等批量任务，按主键分批处理并保存游标，重启后从游标继续
+
+CREATE TABLE IF NOT EXISTS maintenance_jobs (
+    id VARCHAR(36) PRIMARY KEY,
+    type VARCHAR(50),
+    params JSON,
+    status VARCHAR(20),
+    cursor VARCHAR(255),
+    total BIGINT DEFAULT 0,
+    processed BIGINT DEFAULT 0,
+    updated BIGINT DEFAULT 0,
+    error TEXT,
+    created_by VARCHAR(36),
+    active_type VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_jobs_type ON maintenance_jobs(type);
+CREATE INDEX IF NOT EXISTS idx_maintenance_jobs_status ON maintenance_jobs(status);
+CREATE INDEX IF NOT EXISTS idx_maintenance_jobs_created_at ON maintenance_jobs(created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_maintenance_jobs_active_type ON maintenance_jobs(active_type);
+
+COMMENT ON TABLE maintenance_jobs IS '维护任务';
+COMMENT ON COLUMN maintenance_jobs.cursor IS '最近一批处理完的最后一条记录的主键';
+COMMENT ON COLUMN maintenance_jobs.active_type IS '排队或运行中时等于 type，结束后为空；唯一索引保证同类型只有一个活动任务';
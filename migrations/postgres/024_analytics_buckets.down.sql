@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: 使用情况统计
+
+DROP TABLE IF EXISTS analytics_buckets;
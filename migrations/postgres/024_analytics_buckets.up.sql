@@ -0,0 +1,16 @@
+-- PostgreSQL Migration: 使用情况统计
+-- 只保存按小时/天汇总的计数；维度中不含邮箱地址、IP 等可识别个人的数据，发件人以每日轮换盐的哈希记录
+
+CREATE TABLE IF NOT EXISTS analytics_buckets (
+    metric VARCHAR(64) NOT NULL,
+    dimension VARCHAR(255) NOT NULL,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    value BIGINT DEFAULT 0,
+    PRIMARY KEY (metric, dimension, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_analytics_buckets_bucket ON analytics_buckets(bucket);
+
+COMMENT ON TABLE analytics_buckets IS '使用情况统计（汇总计数）';
+COMMENT ON COLUMN analytics_buckets.bucket IS 'UTC 整点（每日指标为 UTC 零点）';
+COMMENT ON COLUMN analytics_buckets.value IS '计数类指标为累计值，峰值类指标为最大值';
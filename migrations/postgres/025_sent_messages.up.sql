@@ -0,0 +1,26 @@
+-- PostgreSQL Migration: 已发送邮件
+-- 邮箱经发信中继发出的邮件（“已发送”文件夹），发信配额按邮箱和用户统计最近 24 小时的记录
+
+CREATE TABLE IF NOT EXISTS sent_messages (
+    id VARCHAR(36) PRIMARY KEY,
+    mailbox_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36),
+    "from" VARCHAR(255),
+    "to" JSON,
+    cc JSON,
+    subject VARCHAR(500),
+    text TEXT,
+    html TEXT,
+    internet_message_id VARCHAR(255),
+    reply_to_message_id VARCHAR(36),
+    size BIGINT DEFAULT 0,
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sent_messages_mailbox_sent ON sent_messages(mailbox_id, sent_at);
+CREATE INDEX IF NOT EXISTS idx_sent_messages_user_sent ON sent_messages(user_id, sent_at);
+
+COMMENT ON TABLE sent_messages IS '已发送邮件';
+COMMENT ON COLUMN sent_messages.user_id IS '发信时邮箱所属用户（游客邮箱为空），用于按用户统计配额';
+COMMENT ON COLUMN sent_messages.internet_message_id IS '邮件头中的 Message-ID（不含尖括号）';
+COMMENT ON COLUMN sent_messages.reply_to_message_id IS '回复的收件邮件ID';
@@ -0,0 +1,5 @@
+-- PostgreSQL Rollback: 收信 DKIM 验证结果
+
+DROP INDEX IF EXISTS idx_messages_dkim_result;
+ALTER TABLE messages DROP COLUMN IF EXISTS dkim_domain;
+ALTER TABLE messages DROP COLUMN IF EXISTS dkim_result;
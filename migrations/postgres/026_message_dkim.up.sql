@@ -0,0 +1,10 @@
+-- PostgreSQL Migration: 收信 DKIM 验证结果
+-- pass/fail/none，未验证的历史邮件为空
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS dkim_result VARCHAR(16);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS dkim_domain VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_messages_dkim_result ON messages(dkim_result);
+
+COMMENT ON COLUMN messages.dkim_result IS 'DKIM 验证结果：pass、fail 或 none（未验证时为空）';
+COMMENT ON COLUMN messages.dkim_domain IS '签名域名（d=）';
@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: 收信 SPF/DMARC 认证结果
+
+ALTER TABLE messages DROP COLUMN IF EXISTS auth_results;
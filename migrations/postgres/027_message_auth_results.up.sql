@@ -0,0 +1,6 @@
+-- PostgreSQL Migration: 收信 SPF/DMARC 认证结果
+-- JSON：spf、dkim、dmarc 结果、From 域名策略及未通过时的处理方式
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS auth_results JSONB;
+
+COMMENT ON COLUMN messages.auth_results IS '发件人认证结果（SPF、DKIM、DMARC），未检查时为空';
@@ -0,0 +1,4 @@
+-- PostgreSQL Rollback: 用户域名通配收件
+
+ALTER TABLE user_domains DROP COLUMN IF EXISTS catch_all_mailbox_id;
+ALTER TABLE user_domains DROP COLUMN IF EXISTS catch_all_enabled;
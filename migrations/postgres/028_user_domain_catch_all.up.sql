@@ -0,0 +1,8 @@
+-- PostgreSQL Migration: 用户域名通配收件
+-- 通配模式的域名接收发往不存在地址的邮件：投递到指定邮箱，或为所有者自动创建邮箱
+
+ALTER TABLE user_domains ADD COLUMN IF NOT EXISTS catch_all_enabled BOOLEAN DEFAULT FALSE;
+ALTER TABLE user_domains ADD COLUMN IF NOT EXISTS catch_all_mailbox_id VARCHAR(36);
+
+COMMENT ON COLUMN user_domains.catch_all_enabled IS '通配收件（仅通配模式的已验证域名生效）';
+COMMENT ON COLUMN user_domains.catch_all_mailbox_id IS '通配邮箱，为空时按收件地址自动创建邮箱';
@@ -0,0 +1,14 @@
+-- PostgreSQL Migration: 用户域名白名单
+-- 白名单模式的域名只允许列表中的前缀创建邮箱和接收邮件
+
+CREATE TABLE IF NOT EXISTS domain_whitelist_entries (
+    id VARCHAR(36) PRIMARY KEY,
+    domain_id VARCHAR(36) NOT NULL,
+    local_part VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_domain_whitelist_domain_local ON domain_whitelist_entries(domain_id, local_part);
+
+COMMENT ON TABLE domain_whitelist_entries IS '用户域名白名单';
+COMMENT ON COLUMN domain_whitelist_entries.local_part IS '允许的邮箱前缀（小写）';
@@ -0,0 +1,4 @@
+-- PostgreSQL Rollback: 邮箱转发
+
+DROP TABLE IF EXISTS forward_deliveries;
+DROP TABLE IF EXISTS mailbox_forwards;
@@ -0,0 +1,40 @@
+-- PostgreSQL Migration: 邮箱转发
+-- 收到的邮件经发信中继复制一份发往已确认的外部地址，投递失败按退避重试
+
+CREATE TABLE IF NOT EXISTS mailbox_forwards (
+    id VARCHAR(36) PRIMARY KEY,
+    mailbox_id VARCHAR(36) NOT NULL,
+    address VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    code_hash VARCHAR(64),
+    code_sent_at TIMESTAMP WITH TIME ZONE,
+    verify_attempts BIGINT DEFAULT 0,
+    verified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_mailbox_forwards_mailbox_id ON mailbox_forwards(mailbox_id);
+
+CREATE TABLE IF NOT EXISTS forward_deliveries (
+    id VARCHAR(36) PRIMARY KEY,
+    forward_id VARCHAR(36) NOT NULL,
+    mailbox_id VARCHAR(36) NOT NULL,
+    message_id VARCHAR(36) NOT NULL,
+    address VARCHAR(255),
+    attempts BIGINT DEFAULT 0,
+    success BOOLEAN DEFAULT FALSE,
+    error TEXT,
+    next_retry TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_forward_deliveries_forward_id ON forward_deliveries(forward_id);
+CREATE INDEX IF NOT EXISTS idx_forward_deliveries_mailbox_id ON forward_deliveries(mailbox_id);
+CREATE INDEX IF NOT EXISTS idx_forward_deliveries_next_retry ON forward_deliveries(next_retry);
+
+COMMENT ON TABLE mailbox_forwards IS '邮箱转发地址';
+COMMENT ON COLUMN mailbox_forwards.status IS '状态：pending（等待确认）、verified（已确认）';
+COMMENT ON COLUMN mailbox_forwards.code_hash IS '确认码的 SHA-256（确认后清空）';
+COMMENT ON TABLE forward_deliveries IS '邮件转发投递队列';
+COMMENT ON COLUMN forward_deliveries.next_retry IS '下次投递时间，成功或放弃后为空';
@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: 邮箱自动续期
+
+ALTER TABLE mailboxes DROP COLUMN IF EXISTS auto_renew;
@@ -0,0 +1,6 @@
+-- PostgreSQL Migration: 邮箱自动续期
+-- 用户邮箱可手动延长有效期或开启自动续期，上限为所属用户等级的最长生存时间
+
+ALTER TABLE mailboxes ADD COLUMN IF NOT EXISTS auto_renew BOOLEAN DEFAULT FALSE;
+
+COMMENT ON COLUMN mailboxes.auto_renew IS '临近过期时由清理任务按默认有效期自动续期';
@@ -0,0 +1,17 @@
+-- PostgreSQL Migration: 邮件全文索引
+-- 入库时把主题、发件人和正文分词后写入索引文档，跨邮箱搜索走 GIN 表达式索引
+-- 已有邮件需运行 cmd/reindex-search 重建索引
+
+CREATE TABLE IF NOT EXISTS message_search_documents (
+    message_id VARCHAR(36) PRIMARY KEY,
+    mailbox_id VARCHAR(36),
+    terms TEXT,
+    received_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_search_documents_mailbox_id ON message_search_documents(mailbox_id);
+CREATE INDEX IF NOT EXISTS idx_message_search_documents_received_at ON message_search_documents(received_at);
+CREATE INDEX IF NOT EXISTS idx_message_search_documents_terms ON message_search_documents USING GIN (to_tsvector('simple', terms));
+
+COMMENT ON TABLE message_search_documents IS '邮件全文索引文档（随邮件删除）';
+COMMENT ON COLUMN message_search_documents.terms IS '分词后去重的小写词项，空格分隔';
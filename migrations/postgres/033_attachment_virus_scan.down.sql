@@ -0,0 +1,4 @@
+-- PostgreSQL Rollback: 附件病毒扫描
+
+ALTER TABLE attachments DROP COLUMN IF EXISTS infected;
+ALTER TABLE attachments DROP COLUMN IF EXISTS threat;
@@ -0,0 +1,8 @@
+-- PostgreSQL Migration: 附件病毒扫描
+-- 入库时由 clamd 扫描附件，检出威胁的邮件投递到隔离区，被标记的附件禁止下载
+
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS infected BOOLEAN DEFAULT FALSE;
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS threat VARCHAR(255);
+
+COMMENT ON COLUMN attachments.infected IS '病毒扫描检出威胁（禁止下载）';
+COMMENT ON COLUMN attachments.threat IS '检出的威胁名称';
@@ -0,0 +1,7 @@
+-- PostgreSQL Rollback: 垃圾邮件判定和用户阈值
+
+DROP INDEX IF EXISTS idx_messages_is_spam;
+ALTER TABLE messages DROP COLUMN IF EXISTS is_spam;
+
+ALTER TABLE users DROP COLUMN IF EXISTS spam_quarantine_score;
+ALTER TABLE users DROP COLUMN IF EXISTS spam_reject_score;
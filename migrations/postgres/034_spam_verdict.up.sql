@@ -0,0 +1,12 @@
+-- PostgreSQL Migration: 垃圾邮件判定和用户阈值
+-- 邮件记录是否判定为垃圾邮件（可按 isSpam 筛选），用户可为名下邮箱设置默认阈值
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS is_spam BOOLEAN DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_messages_is_spam ON messages(is_spam);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS spam_quarantine_score DOUBLE PRECISION;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS spam_reject_score DOUBLE PRECISION;
+
+COMMENT ON COLUMN messages.is_spam IS '评分超过收件人的软阈值（垃圾邮件判定）';
+COMMENT ON COLUMN users.spam_quarantine_score IS '用户自定义软阈值（为空时使用系统配置）';
+COMMENT ON COLUMN users.spam_reject_score IS '用户自定义硬阈值（为空时使用系统配置）';
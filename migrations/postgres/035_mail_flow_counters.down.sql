@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: 收信流量统计
+
+DROP TABLE IF EXISTS mail_flow_counters;
@@ -0,0 +1,17 @@
+-- PostgreSQL Migration: 收信流量统计
+-- SMTP 后端按分钟汇总接收邮件数、拒收次数（按原因）和发件/收件域名，管理后台据此查看流量突增
+
+CREATE TABLE IF NOT EXISTS mail_flow_counters (
+    kind VARCHAR(32) NOT NULL,
+    dimension VARCHAR(255) NOT NULL,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    count BIGINT DEFAULT 0,
+    PRIMARY KEY (kind, dimension, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mail_flow_counters_bucket ON mail_flow_counters(bucket);
+
+COMMENT ON TABLE mail_flow_counters IS '收信流量计数（按分钟）';
+COMMENT ON COLUMN mail_flow_counters.kind IS 'accepted、rejected、sender_domain 或 recipient_domain';
+COMMENT ON COLUMN mail_flow_counters.dimension IS '拒绝原因或域名（accepted 为空）';
+COMMENT ON COLUMN mail_flow_counters.bucket IS 'UTC 整分钟';
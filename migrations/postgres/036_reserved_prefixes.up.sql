@@ -0,0 +1,20 @@
+-- PostgreSQL Migration: 系统域名保留前缀
+-- 管理员屏蔽的前缀（user_id 为空）任何人都不能创建；付费用户预留的靓号前缀在有效期内只有本人可以创建
+
+CREATE TABLE IF NOT EXISTS reserved_prefixes (
+    id VARCHAR(36) PRIMARY KEY,
+    domain VARCHAR(255) NOT NULL,
+    local_part VARCHAR(64) NOT NULL,
+    user_id VARCHAR(36),
+    reason VARCHAR(255),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reserved_prefixes_domain_local ON reserved_prefixes(domain, local_part);
+CREATE INDEX IF NOT EXISTS idx_reserved_prefixes_user_id ON reserved_prefixes(user_id);
+
+COMMENT ON TABLE reserved_prefixes IS '系统域名保留前缀';
+COMMENT ON COLUMN reserved_prefixes.domain IS '小写域名，空字符串表示所有系统域名';
+COMMENT ON COLUMN reserved_prefixes.user_id IS '预留给该用户，为空表示禁止使用';
+COMMENT ON COLUMN reserved_prefixes.expires_at IS '过期时间，为空表示永久';
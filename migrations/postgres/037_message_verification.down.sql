@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: 邮件验证码提取
+
+ALTER TABLE messages DROP COLUMN IF EXISTS verification;
@@ -0,0 +1,6 @@
+-- PostgreSQL Migration: 邮件验证码提取
+-- 入库时从正文提取的一次性验证码和验证链接（JSON：code、codes、links）
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS verification JSONB;
+
+COMMENT ON COLUMN messages.verification IS '入库时提取的验证码和验证链接';
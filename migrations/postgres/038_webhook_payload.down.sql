@@ -0,0 +1,4 @@
+-- PostgreSQL Rollback: Webhook 回调内容格式
+
+ALTER TABLE webhooks DROP COLUMN IF EXISTS payload_template;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS payload_format;
@@ -0,0 +1,5 @@
+-- PostgreSQL Rollback: Webhook 死信
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_dead_letter;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS redelivered_at;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS dead_letter;
@@ -0,0 +1,10 @@
+-- PostgreSQL Migration: Webhook 死信
+-- 重试用尽的投递标记为死信，可通过 /v1/webhooks/:id/deliveries/:deliveryId/redeliver 人工重新投递
+
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS dead_letter BOOLEAN DEFAULT FALSE;
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS redelivered_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_dead_letter ON webhook_deliveries(dead_letter);
+
+COMMENT ON COLUMN webhook_deliveries.dead_letter IS '重试用尽，等待人工重新投递';
+COMMENT ON COLUMN webhook_deliveries.redelivered_at IS '人工重新投递的时间（之后不再列入死信）';
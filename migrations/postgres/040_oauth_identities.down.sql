@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: 第三方登录身份
+
+DROP TABLE IF EXISTS oauth_identities;
@@ -0,0 +1,20 @@
+-- PostgreSQL Migration: 第三方登录身份
+-- 用户通过 Google、GitHub 或自定义 OIDC IdP 登录时关联的外部账号；同一提供方的账号只能关联一个用户
+
+CREATE TABLE IF NOT EXISTS oauth_identities (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE,
+    last_login_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oauth_identities_provider_subject ON oauth_identities(provider, subject);
+CREATE INDEX IF NOT EXISTS idx_oauth_identities_user_id ON oauth_identities(user_id);
+
+COMMENT ON TABLE oauth_identities IS '第三方登录身份';
+COMMENT ON COLUMN oauth_identities.provider IS '提供方名称：google、github 或自定义 OIDC 名称';
+COMMENT ON COLUMN oauth_identities.subject IS '提供方的用户唯一标识（OIDC sub、GitHub 用户 ID）';
+COMMENT ON COLUMN oauth_identities.email IS '关联时提供方返回的邮箱';
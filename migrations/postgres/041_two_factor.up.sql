@@ -0,0 +1,12 @@
+-- PostgreSQL Migration: 两步验证（TOTP）
+-- 密钥加密保存，设置后验证通过才启用；恢复码只保存 SHA-256 摘要
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS recovery_codes JSONB;
+
+COMMENT ON COLUMN users.totp_secret IS 'TOTP 密钥（AES-GCM 加密）';
+COMMENT ON COLUMN users.totp_enabled IS '是否已启用两步验证';
+COMMENT ON COLUMN users.totp_last_step IS '最近一次使用的验证码时间步（防重放）';
+COMMENT ON COLUMN users.recovery_codes IS '未使用的恢复码摘要';
@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: 登录会话
+
+DROP TABLE IF EXISTS user_sessions;
@@ -0,0 +1,23 @@
+-- PostgreSQL Migration: 登录会话
+-- 每次登录一个会话；刷新时轮换令牌并记录当前刷新令牌 jti，重放旧刷新令牌时整个会话作废
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    refresh_token_id VARCHAR(36) NOT NULL,
+    access_token_id VARCHAR(36),
+    access_expires_at TIMESTAMP WITH TIME ZONE,
+    ip VARCHAR(45),
+    user_agent VARCHAR(512),
+    created_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_expires_at ON user_sessions(expires_at);
+
+COMMENT ON TABLE user_sessions IS '登录会话';
+COMMENT ON COLUMN user_sessions.refresh_token_id IS '当前有效的刷新令牌 jti';
+COMMENT ON COLUMN user_sessions.access_token_id IS '最近签发的访问令牌 jti（撤销会话时加入黑名单）';
+COMMENT ON COLUMN user_sessions.expires_at IS '刷新令牌过期时间';
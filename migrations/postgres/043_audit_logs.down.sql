@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: 管理操作审计记录
+
+DROP TABLE IF EXISTS audit_logs;
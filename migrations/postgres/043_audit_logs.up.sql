@@ -0,0 +1,25 @@
+-- PostgreSQL Migration: 管理操作审计记录
+-- 每个成功的管理写操作一条记录（操作者、操作、目标、字段变更），只追加
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id VARCHAR(36) PRIMARY KEY,
+    actor_id VARCHAR(36),
+    actor_email VARCHAR(255),
+    action VARCHAR(128),
+    target_type VARCHAR(32),
+    target_id VARCHAR(255),
+    changes JSON,
+    note VARCHAR(512),
+    ip VARCHAR(45),
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs(actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target ON audit_logs(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+
+COMMENT ON TABLE audit_logs IS '管理操作审计记录';
+COMMENT ON COLUMN audit_logs.action IS '操作，如 user.update、domain.delete、config.update';
+COMMENT ON COLUMN audit_logs.changes IS '字段变更列表（field、from、to）';
+COMMENT ON COLUMN audit_logs.note IS '附加说明（如代为登录的原因）';
@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: 事务性发件箱
+
+DROP TABLE IF EXISTS outbox_events;
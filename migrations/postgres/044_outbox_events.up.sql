@@ -0,0 +1,27 @@
+-- PostgreSQL Migration: 事务性发件箱
+-- 新邮件事件与邮件在同一事务写入，由分发器投递到 WebSocket、Webhook 等目标后标记为已处理
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id VARCHAR(36) PRIMARY KEY,
+    type VARCHAR(64),
+    mailbox_id VARCHAR(36) NOT NULL,
+    message_id VARCHAR(36) NOT NULL,
+    delivered JSON,
+    attempts BIGINT DEFAULT 0,
+    last_error TEXT,
+    claim_token VARCHAR(36),
+    claimed_until TIMESTAMP WITH TIME ZONE,
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    processed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_claim_token ON outbox_events(claim_token);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_next_attempt_at ON outbox_events(next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_processed_at ON outbox_events(processed_at);
+
+COMMENT ON TABLE outbox_events IS '事务性发件箱事件';
+COMMENT ON COLUMN outbox_events.delivered IS '已完成的投递目标，重试时跳过';
+COMMENT ON COLUMN outbox_events.claim_token IS '最近一次认领的令牌';
+COMMENT ON COLUMN outbox_events.claimed_until IS '认领到期时间，之前其他分发器不会取到';
+COMMENT ON COLUMN outbox_events.processed_at IS '全部目标投递完成（或放弃）的时间';
@@ -0,0 +1,3 @@
+-- PostgreSQL Rollback: 应用专用密码代数
+
+ALTER TABLE users DROP COLUMN IF EXISTS app_password_generation;
@@ -0,0 +1,6 @@
+-- PostgreSQL Migration: 应用专用密码代数
+-- 参与派生应用密码，重置应用密码、修改密码、注销全部会话时递增，旧应用密码随之失效
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS app_password_generation BIGINT DEFAULT 0;
+
+COMMENT ON COLUMN users.app_password_generation IS '应用专用密码代数';
@@ -0,0 +1,32 @@
+-- SQLite Rollback: 初始化数据库结构
+
+DROP TABLE IF EXISTS `webhooks`;
+DROP TABLE IF EXISTS `webhook_deliveries`;
+DROP TABLE IF EXISTS `users`;
+DROP TABLE IF EXISTS `user_domains`;
+DROP TABLE IF EXISTS `tags`;
+DROP TABLE IF EXISTS `system_domains`;
+DROP TABLE IF EXISTS `sent_messages`;
+DROP TABLE IF EXISTS `reserved_prefixes`;
+DROP TABLE IF EXISTS `organizations`;
+DROP TABLE IF EXISTS `org_members`;
+DROP TABLE IF EXISTS `org_invites`;
+DROP TABLE IF EXISTS `oauth_identities`;
+DROP TABLE IF EXISTS `messages`;
+DROP TABLE IF EXISTS `message_tags`;
+DROP TABLE IF EXISTS `message_shares`;
+DROP TABLE IF EXISTS `message_sequences`;
+DROP TABLE IF EXISTS `message_search_documents`;
+DROP TABLE IF EXISTS `message_redactions`;
+DROP TABLE IF EXISTS `maintenance_jobs`;
+DROP TABLE IF EXISTS `mailboxes`;
+DROP TABLE IF EXISTS `mailbox_forwards`;
+DROP TABLE IF EXISTS `mailbox_aliases`;
+DROP TABLE IF EXISTS `forward_deliveries`;
+DROP TABLE IF EXISTS `domain_whitelist_entries`;
+DROP TABLE IF EXISTS `distribution_lists`;
+DROP TABLE IF EXISTS `distribution_list_deliveries`;
+DROP TABLE IF EXISTS `attachments`;
+DROP TABLE IF EXISTS `api_keys`;
+DROP TABLE IF EXISTS `analytics_buckets`;
+DROP TABLE IF EXISTS `abuse_reports`;
@@ -0,0 +1,583 @@
+-- SQLite Migration: 初始化数据库结构
+-- 单文件部署使用。服务启动时会自动建表，本脚本用于预先建库或手动部署；
+-- 结构与 GORM 模型保持一致（由 AutoMigrate 生成），模型变更时需要同步更新。
+
+CREATE TABLE IF NOT EXISTS `abuse_reports` (
+    `id` varchar(36),
+    `reporter_email` varchar(254),
+    `reporter_ip` varchar(64),
+    `target` varchar(512),
+    `category` varchar(20),
+    `details` text,
+    `status` varchar(20),
+    `mailbox_id` varchar(36),
+    `message_id` varchar(36),
+    `priority` numeric DEFAULT false,
+    `action` varchar(20),
+    `note` text,
+    `resolved_by` varchar(36),
+    `created_at` datetime,
+    `updated_at` datetime,
+    `resolved_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `analytics_buckets` (
+    `metric` varchar(64),
+    `dimension` varchar(255),
+    `bucket` datetime,
+    `value` integer DEFAULT 0,
+    PRIMARY KEY (`metric`,`dimension`,`bucket`)
+);
+
+CREATE TABLE IF NOT EXISTS `api_keys` (
+    `id` varchar(36),
+    `user_id` varchar(36) NOT NULL,
+    `key_hash` varchar(255) NOT NULL,
+    `key_prefix` varchar(20) NOT NULL,
+    `name` varchar(100),
+    `scopes` json,
+    `is_active` numeric,
+    `created_at` datetime,
+    `expires_at` datetime,
+    `last_used_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `attachments` (
+    `id` varchar(36),
+    `message_id` varchar(36) NOT NULL,
+    `filename` varchar(255),
+    `content_type` varchar(100),
+    `size` integer,
+    `storage_path` varchar(500),
+    `detected_content_type` varchar(100),
+    `content_type_mismatch` numeric DEFAULT false,
+    `infected` numeric DEFAULT false,
+    `threat` varchar(255),
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `audit_logs` (
+    `id` varchar(36),
+    `actor_id` varchar(36),
+    `actor_email` varchar(255),
+    `action` varchar(128),
+    `target_type` varchar(32),
+    `target_id` varchar(255),
+    `changes` json,
+    `note` varchar(512),
+    `ip` varchar(45),
+    `created_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `distribution_list_deliveries` (
+    `id` varchar(36),
+    `list_id` varchar(36) NOT NULL,
+    `from` varchar(255),
+    `subject` varchar(500),
+    `delivered` json,
+    `failures` json,
+    `created_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `distribution_lists` (
+    `id` varchar(36),
+    `user_id` varchar(36) NOT NULL,
+    `org_id` varchar(36),
+    `name` varchar(100),
+    `address` varchar(255) NOT NULL,
+    `members` json,
+    `is_active` numeric DEFAULT true,
+    `created_at` datetime,
+    `updated_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `domain_whitelist_entries` (
+    `id` varchar(36),
+    `domain_id` varchar(36) NOT NULL,
+    `local_part` varchar(64) NOT NULL,
+    `created_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `forward_deliveries` (
+    `id` varchar(36),
+    `forward_id` varchar(36) NOT NULL,
+    `mailbox_id` varchar(36) NOT NULL,
+    `message_id` varchar(36) NOT NULL,
+    `address` varchar(255),
+    `attempts` integer DEFAULT 0,
+    `success` numeric DEFAULT false,
+    `error` text,
+    `next_retry` datetime,
+    `created_at` datetime,
+    `updated_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `mail_flow_counters` (
+    `kind` varchar(32),
+    `dimension` varchar(255),
+    `bucket` datetime,
+    `count` integer DEFAULT 0,
+    PRIMARY KEY (`kind`,`dimension`,`bucket`)
+);
+
+CREATE TABLE IF NOT EXISTS `mailbox_aliases` (
+    `id` varchar(36),
+    `mailbox_id` varchar(36) NOT NULL,
+    `address` varchar(255),
+    `created_at` datetime,
+    `is_active` numeric,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `mailbox_forwards` (
+    `id` varchar(36),
+    `mailbox_id` varchar(36) NOT NULL,
+    `address` varchar(255) NOT NULL,
+    `status` varchar(20) NOT NULL,
+    `code_hash` varchar(64),
+    `code_sent_at` datetime,
+    `verify_attempts` integer DEFAULT 0,
+    `verified_at` datetime,
+    `created_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `mailboxes` (
+    `id` varchar(36),
+    `address` varchar(255),
+    `local_part` varchar(255),
+    `domain` varchar(100),
+    `token` varchar(255),
+    `user_id` varchar(36),
+    `org_id` varchar(36),
+    `created_at` datetime,
+    `expires_at` datetime,
+    `ip_source` text,
+    `total_count` integer,
+    `unread` integer,
+    `spam_quarantine_score` real,
+    `spam_reject_score` real,
+    `last_accessed_at` datetime,
+    `idle_since` datetime,
+    `idle_shortened` numeric,
+    `idle_original_expires_at` datetime,
+    `is_public` numeric DEFAULT false,
+    `suspended` numeric DEFAULT false,
+    `expiry_rules` json,
+    `auto_renew` numeric DEFAULT false,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `maintenance_jobs` (
+    `id` varchar(36),
+    `type` varchar(50),
+    `params` json,
+    `status` varchar(20),
+    `cursor` varchar(255),
+    `total` integer DEFAULT 0,
+    `processed` integer DEFAULT 0,
+    `updated` integer DEFAULT 0,
+    `error` text,
+    `created_by` varchar(36),
+    `active_type` varchar(50),
+    `created_at` datetime,
+    `updated_at` datetime,
+    `started_at` datetime,
+    `finished_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `message_redactions` (
+    `message_id` varchar(36),
+    `mailbox_id` varchar(36) NOT NULL,
+    `rules_version` integer,
+    `rules` json,
+    `patterns` json,
+    `subject` varchar(500),
+    `from` varchar(255),
+    `text` text,
+    `html` text,
+    `created_at` datetime,
+    PRIMARY KEY (`message_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `message_search_documents` (
+    `message_id` varchar(36),
+    `mailbox_id` varchar(36),
+    `terms` text,
+    `received_at` datetime,
+    PRIMARY KEY (`message_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `message_sequences` (
+    `mailbox_id` varchar(36),
+    `last_seq` integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (`mailbox_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `message_shares` (
+    `id` varchar(36),
+    `mailbox_id` varchar(36) NOT NULL,
+    `message_id` varchar(36) NOT NULL,
+    `nonce` varchar(64) NOT NULL,
+    `allow_attachments` numeric DEFAULT false,
+    `redacted` numeric DEFAULT false,
+    `views` integer DEFAULT 0,
+    `last_viewed_at` datetime,
+    `expires_at` datetime,
+    `revoked_at` datetime,
+    `created_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `message_tags` (
+    `message_id` varchar(36),
+    `tag_id` varchar(36),
+    `created_at` datetime,
+    PRIMARY KEY (`message_id`,`tag_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `messages` (
+    `id` varchar(36),
+    `mailbox_id` varchar(36) NOT NULL,
+    `seq` integer DEFAULT 0,
+    `from` varchar(255),
+    `to` varchar(255),
+    `subject` varchar(500),
+    `created_at` datetime,
+    `is_read` numeric DEFAULT false,
+    `received_at` datetime,
+    `size` integer DEFAULT 0,
+    `has_raw` numeric DEFAULT false,
+    `has_html` numeric DEFAULT false,
+    `has_text` numeric DEFAULT false,
+    `text_derived_from_html` numeric DEFAULT false,
+    `detected_language` varchar(8),
+    `spam_score` real DEFAULT 0,
+    `spam_action` varchar(32),
+    `spam_symbols` json,
+    `quarantined` numeric DEFAULT false,
+    `is_spam` numeric DEFAULT false,
+    `dkim_result` varchar(16),
+    `dkim_domain` varchar(255),
+    `auth_results` json,
+    `captured_headers` json,
+    `verification` json,
+    `expires_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `oauth_identities` (
+    `id` varchar(36),
+    `user_id` varchar(36) NOT NULL,
+    `provider` varchar(50) NOT NULL,
+    `subject` varchar(255) NOT NULL,
+    `email` varchar(255),
+    `created_at` datetime,
+    `last_login_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `org_invites` (
+    `token` varchar(64),
+    `org_id` varchar(36) NOT NULL,
+    `email` varchar(255) NOT NULL,
+    `invited_by` varchar(36),
+    `created_at` datetime,
+    `expires_at` datetime,
+    PRIMARY KEY (`token`)
+);
+
+CREATE TABLE IF NOT EXISTS `org_members` (
+    `org_id` varchar(36),
+    `user_id` varchar(36),
+    `role` varchar(20) DEFAULT 'member',
+    `joined_at` datetime,
+    PRIMARY KEY (`org_id`,`user_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `organizations` (
+    `id` varchar(36),
+    `name` varchar(100) NOT NULL,
+    `owner_id` varchar(36) NOT NULL,
+    `tier` varchar(20) DEFAULT 'free',
+    `created_at` datetime,
+    `updated_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+    `id` varchar(36),
+    `type` varchar(64),
+    `mailbox_id` varchar(36) NOT NULL,
+    `message_id` varchar(36) NOT NULL,
+    `delivered` json,
+    `attempts` integer DEFAULT 0,
+    `last_error` text,
+    `claim_token` varchar(36),
+    `claimed_until` datetime,
+    `next_attempt_at` datetime,
+    `processed_at` datetime,
+    `created_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `reserved_prefixes` (
+    `id` varchar(36),
+    `domain` varchar(255) NOT NULL,
+    `local_part` varchar(64) NOT NULL,
+    `user_id` varchar(36),
+    `reason` varchar(255),
+    `expires_at` datetime,
+    `created_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `sent_messages` (
+    `id` varchar(36),
+    `mailbox_id` varchar(36) NOT NULL,
+    `user_id` varchar(36),
+    `from` varchar(255),
+    `to` json,
+    `cc` json,
+    `subject` varchar(500),
+    `text` text,
+    `html` text,
+    `internet_message_id` varchar(255),
+    `reply_to_message_id` varchar(36),
+    `size` integer DEFAULT 0,
+    `sent_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `system_domains` (
+    `id` varchar(36),
+    `domain` varchar(100) NOT NULL,
+    `status` varchar(20) DEFAULT 'pending',
+    `verify_token` varchar(255),
+    `verify_method` varchar(20) DEFAULT 'dns_txt',
+    `verified_at` datetime,
+    `last_check_at` datetime,
+    `created_at` datetime,
+    `created_by` varchar(36),
+    `is_active` numeric DEFAULT false,
+    `is_default` numeric DEFAULT false,
+    `mx_records` json,
+    `mailbox_count` integer DEFAULT 0,
+    `notes` text,
+    `sink_enabled` numeric DEFAULT false,
+    `sink_sample_rate` integer DEFAULT 0,
+    `sink_diagnostics_mailbox_id` varchar(36),
+    `allow_public_inboxes` numeric DEFAULT false,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tags` (
+    `id` varchar(36),
+    `user_id` varchar(36) NOT NULL,
+    `org_id` varchar(36),
+    `name` text,
+    `color` text,
+    `description` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `user_domains` (
+    `id` varchar(36),
+    `user_id` varchar(36) NOT NULL,
+    `org_id` varchar(36),
+    `domain` varchar(100) NOT NULL,
+    `mode` varchar(20) DEFAULT 'shared',
+    `status` varchar(20) DEFAULT 'pending',
+    `verify_token` varchar(255),
+    `verify_method` varchar(20) DEFAULT 'dns_txt',
+    `verified_at` datetime,
+    `last_check_at` datetime,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `expires_at` datetime,
+    `grace_notices` integer DEFAULT 0,
+    `mx_records` json,
+    `is_active` numeric DEFAULT false,
+    `mailbox_count` integer DEFAULT 0,
+    `monthly_fee` decimal(10,2) DEFAULT 0,
+    `notes` text,
+    `sink_enabled` numeric DEFAULT false,
+    `sink_sample_rate` integer DEFAULT 0,
+    `sink_diagnostics_mailbox_id` varchar(36),
+    `catch_all_enabled` numeric DEFAULT false,
+    `catch_all_mailbox_id` varchar(36),
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `user_sessions` (
+    `id` varchar(36),
+    `user_id` varchar(36) NOT NULL,
+    `refresh_token_id` varchar(36) NOT NULL,
+    `access_token_id` varchar(36),
+    `access_expires_at` datetime,
+    `ip` varchar(45),
+    `user_agent` varchar(512),
+    `created_at` datetime,
+    `last_used_at` datetime,
+    `expires_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `users` (
+    `id` varchar(36),
+    `email` varchar(255) NOT NULL,
+    `username` varchar(100),
+    `password_hash` varchar(255),
+    `role` varchar(20) DEFAULT 'user',
+    `tier` varchar(20) DEFAULT 'free',
+    `is_active` numeric DEFAULT true,
+    `is_email_verified` numeric DEFAULT false,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `last_login_at` datetime,
+    `locked_until` datetime,
+    `recent_login_ips` json,
+    `spam_quarantine_score` real,
+    `spam_reject_score` real,
+    `totp_secret` varchar(255),
+    `totp_enabled` numeric DEFAULT false,
+    `totp_last_step` integer DEFAULT 0,
+    `recovery_codes` json,
+    `app_password_generation` integer DEFAULT 0,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+    `id` text,
+    `webhook_id` text,
+    `mailbox_id` varchar(36),
+    `event` text,
+    `payload` text,
+    `status_code` integer,
+    `response` text,
+    `duration` integer,
+    `success` numeric,
+    `error` text,
+    `error_kind` varchar(32),
+    `attempts` integer,
+    `next_retry` datetime,
+    `dead_letter` numeric DEFAULT false,
+    `redelivered_at` datetime,
+    `created_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `webhooks` (
+    `id` varchar(36),
+    `user_id` varchar(36) NOT NULL,
+    `org_id` varchar(36),
+    `owner_type` varchar(16) DEFAULT 'user',
+    `owner_id` varchar(36),
+    `url` varchar(500) NOT NULL,
+    `events` json,
+    `secret` varchar(255),
+    `payload_format` varchar(16) DEFAULT 'headers',
+    `payload_template` text,
+    `is_active` numeric DEFAULT true,
+    `retry_count` integer DEFAULT 0,
+    `last_error` text,
+    `last_success` datetime,
+    `created_at` datetime,
+    `updated_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_api_keys_key` ON `api_keys`(`key_hash`);
+CREATE INDEX IF NOT EXISTS `idx_abuse_reports_created_at` ON `abuse_reports`(`created_at`);
+CREATE INDEX IF NOT EXISTS `idx_abuse_reports_mailbox_id` ON `abuse_reports`(`mailbox_id`);
+CREATE INDEX IF NOT EXISTS `idx_abuse_reports_priority` ON `abuse_reports`(`priority`);
+CREATE INDEX IF NOT EXISTS `idx_abuse_reports_status` ON `abuse_reports`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_analytics_buckets_bucket` ON `analytics_buckets`(`bucket`);
+CREATE INDEX IF NOT EXISTS `idx_api_keys_user_id` ON `api_keys`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_attachments_message_id` ON `attachments`(`message_id`);
+CREATE INDEX IF NOT EXISTS `idx_audit_logs_action` ON `audit_logs`(`action`);
+CREATE INDEX IF NOT EXISTS `idx_audit_logs_actor_id` ON `audit_logs`(`actor_id`);
+CREATE INDEX IF NOT EXISTS `idx_audit_logs_created_at` ON `audit_logs`(`created_at`);
+CREATE INDEX IF NOT EXISTS `idx_audit_logs_target` ON `audit_logs`(`target_type`,`target_id`);
+CREATE INDEX IF NOT EXISTS `idx_distribution_list_deliveries_created_at` ON `distribution_list_deliveries`(`created_at`);
+CREATE INDEX IF NOT EXISTS `idx_distribution_list_deliveries_list_id` ON `distribution_list_deliveries`(`list_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_distribution_lists_address` ON `distribution_lists`(`address`);
+CREATE INDEX IF NOT EXISTS `idx_distribution_lists_org_id` ON `distribution_lists`(`org_id`);
+CREATE INDEX IF NOT EXISTS `idx_distribution_lists_user_id` ON `distribution_lists`(`user_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_domain_whitelist_domain_local` ON `domain_whitelist_entries`(`domain_id`,`local_part`);
+CREATE INDEX IF NOT EXISTS `idx_forward_deliveries_forward_id` ON `forward_deliveries`(`forward_id`);
+CREATE INDEX IF NOT EXISTS `idx_forward_deliveries_mailbox_id` ON `forward_deliveries`(`mailbox_id`);
+CREATE INDEX IF NOT EXISTS `idx_forward_deliveries_next_retry` ON `forward_deliveries`(`next_retry`);
+CREATE INDEX IF NOT EXISTS `idx_mail_flow_counters_bucket` ON `mail_flow_counters`(`bucket`);
+CREATE INDEX IF NOT EXISTS `idx_mailbox_aliases_address` ON `mailbox_aliases`(`address`);
+CREATE INDEX IF NOT EXISTS `idx_mailbox_aliases_mailbox_id` ON `mailbox_aliases`(`mailbox_id`);
+CREATE INDEX IF NOT EXISTS `idx_mailbox_forwards_mailbox_id` ON `mailbox_forwards`(`mailbox_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_mailboxes_address` ON `mailboxes`(`address`);
+CREATE INDEX IF NOT EXISTS `idx_mailboxes_domain` ON `mailboxes`(`domain`);
+CREATE INDEX IF NOT EXISTS `idx_mailboxes_is_public` ON `mailboxes`(`is_public`);
+CREATE INDEX IF NOT EXISTS `idx_mailboxes_last_accessed_at` ON `mailboxes`(`last_accessed_at`);
+CREATE INDEX IF NOT EXISTS `idx_mailboxes_org_id` ON `mailboxes`(`org_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_mailboxes_token` ON `mailboxes`(`token`);
+CREATE INDEX IF NOT EXISTS `idx_mailboxes_user_id` ON `mailboxes`(`user_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_maintenance_jobs_active_type` ON `maintenance_jobs`(`active_type`);
+CREATE INDEX IF NOT EXISTS `idx_maintenance_jobs_created_at` ON `maintenance_jobs`(`created_at`);
+CREATE INDEX IF NOT EXISTS `idx_maintenance_jobs_status` ON `maintenance_jobs`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_maintenance_jobs_type` ON `maintenance_jobs`(`type`);
+CREATE INDEX IF NOT EXISTS `idx_message_redactions_mailbox_id` ON `message_redactions`(`mailbox_id`);
+CREATE INDEX IF NOT EXISTS `idx_message_search_documents_mailbox_id` ON `message_search_documents`(`mailbox_id`);
+CREATE INDEX IF NOT EXISTS `idx_message_search_documents_received_at` ON `message_search_documents`(`received_at`);
+CREATE INDEX IF NOT EXISTS `idx_message_shares_mailbox_id` ON `message_shares`(`mailbox_id`);
+CREATE INDEX IF NOT EXISTS `idx_message_shares_message_id` ON `message_shares`(`message_id`);
+CREATE INDEX IF NOT EXISTS `idx_messages_dkim_result` ON `messages`(`dkim_result`);
+CREATE INDEX IF NOT EXISTS `idx_messages_detected_language` ON `messages`(`detected_language`);
+CREATE INDEX IF NOT EXISTS `idx_messages_expires_at` ON `messages`(`expires_at`);
+CREATE INDEX IF NOT EXISTS `idx_messages_is_read` ON `messages`(`is_read`);
+CREATE INDEX IF NOT EXISTS `idx_messages_is_spam` ON `messages`(`is_spam`);
+CREATE INDEX IF NOT EXISTS `idx_messages_mailbox_id` ON `messages`(`mailbox_id`);
+CREATE INDEX IF NOT EXISTS `idx_messages_mailbox_received` ON `messages`(`mailbox_id`,`received_at`);
+CREATE INDEX IF NOT EXISTS `idx_messages_mailbox_seq` ON `messages`(`mailbox_id`,`seq`);
+CREATE INDEX IF NOT EXISTS `idx_messages_quarantined` ON `messages`(`quarantined`);
+CREATE INDEX IF NOT EXISTS `idx_messages_spam_score` ON `messages`(`spam_score`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_oauth_identities_provider_subject` ON `oauth_identities`(`provider`,`subject`);
+CREATE INDEX IF NOT EXISTS `idx_oauth_identities_user_id` ON `oauth_identities`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_org_invites_org_id` ON `org_invites`(`org_id`);
+CREATE INDEX IF NOT EXISTS `idx_org_members_user_id` ON `org_members`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_organizations_owner_id` ON `organizations`(`owner_id`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_claim_token` ON `outbox_events`(`claim_token`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_next_attempt_at` ON `outbox_events`(`next_attempt_at`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_processed_at` ON `outbox_events`(`processed_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_reserved_prefixes_domain_local` ON `reserved_prefixes`(`domain`,`local_part`);
+CREATE INDEX IF NOT EXISTS `idx_reserved_prefixes_user_id` ON `reserved_prefixes`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_sent_messages_mailbox_sent` ON `sent_messages`(`mailbox_id`,`sent_at`);
+CREATE INDEX IF NOT EXISTS `idx_sent_messages_user_sent` ON `sent_messages`(`user_id`,`sent_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_system_domains_domain` ON `system_domains`(`domain`);
+CREATE INDEX IF NOT EXISTS `idx_system_domains_is_active` ON `system_domains`(`is_active`);
+CREATE INDEX IF NOT EXISTS `idx_system_domains_is_default` ON `system_domains`(`is_default`);
+CREATE INDEX IF NOT EXISTS `idx_system_domains_status` ON `system_domains`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_tags_org_id` ON `tags`(`org_id`);
+CREATE INDEX IF NOT EXISTS `idx_tags_user_id` ON `tags`(`user_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_user_domains_domain` ON `user_domains`(`domain`);
+CREATE INDEX IF NOT EXISTS `idx_user_domains_is_active` ON `user_domains`(`is_active`);
+CREATE INDEX IF NOT EXISTS `idx_user_domains_org_id` ON `user_domains`(`org_id`);
+CREATE INDEX IF NOT EXISTS `idx_user_domains_status` ON `user_domains`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_user_domains_user_id` ON `user_domains`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_user_sessions_expires_at` ON `user_sessions`(`expires_at`);
+CREATE INDEX IF NOT EXISTS `idx_user_sessions_user_id` ON `user_sessions`(`user_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_email` ON `users`(`email`);
+CREATE INDEX IF NOT EXISTS `idx_users_role` ON `users`(`role`);
+CREATE INDEX IF NOT EXISTS `idx_users_tier` ON `users`(`tier`);
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_dead_letter` ON `webhook_deliveries`(`dead_letter`);
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_mailbox_id` ON `webhook_deliveries`(`mailbox_id`);
+CREATE INDEX IF NOT EXISTS `idx_webhooks_org_id` ON `webhooks`(`org_id`);
+CREATE INDEX IF NOT EXISTS `idx_webhooks_owner` ON `webhooks`(`owner_type`,`owner_id`);
+CREATE INDEX IF NOT EXISTS `idx_webhooks_user_id` ON `webhooks`(`user_id`);
@@ -0,0 +1,455 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrWaitTimeout WaitForMessage/WaitForOTP 超过等待时长
+var ErrWaitTimeout = errors.New("timed out waiting for message")
+
+// 默认参数
+const (
+	DefaultTimeout      = 30 * time.Second // 单次请求超时
+	DefaultWaitTimeout  = time.Minute      // 等待邮件的默认时长
+	DefaultPollInterval = time.Second      // 等待邮件时的轮询间隔
+	// maxErrorBody 读取错误响应体的最大字节数
+	maxErrorBody = 64 << 10
+)
+
+// Client v1 REST API 客户端
+//
+// 邮箱接口凭 Mailbox.Token 访问（X-Mailbox-Token），Token 为空时使用 SetAccessToken 设置的
+// 用户访问令牌或 SetAPIKey 设置的 API Key（邮箱所有者和组织成员）。默认的 http.Client 按服务端的限流提示自动重试。
+type Client struct {
+	baseURL     string
+	http        *http.Client
+	accessToken string
+	apiKey      string
+}
+
+// New 创建客户端，baseURL 为服务地址（如 https://api.example.com，不含 /v1）
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    NewHTTPClient(DefaultTimeout),
+	}
+}
+
+// SetHTTPClient 替换底层 http.Client
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.http = httpClient
+}
+
+// SetAccessToken 设置用户访问令牌：创建的邮箱归属该用户，也可访问有权限的邮箱
+func (c *Client) SetAccessToken(token string) {
+	c.accessToken = token
+}
+
+// SetAPIKey 设置 API Key（X-API-Key），作用同 SetAccessToken，同时设置时优先使用访问令牌
+func (c *Client) SetAPIKey(key string) {
+	c.apiKey = key
+}
+
+// APIError 服务端返回的错误
+type APIError struct {
+	StatusCode int    // HTTP 状态码
+	Code       int    // 业务状态码（部分错误没有）
+	Message    string // 错误信息
+	Reason     string // 结构化原因（如地址冲突的 ADDRESS_TAKEN）
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("tempmail: %d %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound 错误是否为 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// CreateMailboxRequest 创建邮箱的参数，字段都可为空
+type CreateMailboxRequest struct {
+	Prefix    string        `json:"prefix,omitempty"`
+	Domain    string        `json:"domain,omitempty"`
+	ExpiresIn time.Duration `json:"-"`                   // 有效期，为 0 时使用默认有效期
+	OrgID     string        `json:"orgId,omitempty"`     // 创建为组织邮箱（需要访问令牌）
+	Public    bool          `json:"public,omitempty"`    // 公开收件箱
+	AutoRenew bool          `json:"autoRenew,omitempty"` // 自动续期（需要访问令牌）
+}
+
+// Mailbox 邮箱
+//
+// 只有 ID 和 Token 时也可用于访问邮箱（如从配置中读取的已有邮箱）。
+type Mailbox struct {
+	ID        string     `json:"id"`
+	Address   string     `json:"address"`
+	LocalPart string     `json:"localPart"`
+	Domain    string     `json:"domain"`
+	Token     string     `json:"token"`
+	OrgID     *string    `json:"orgId,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Unread    int        `json:"unread"`
+	Total     int        `json:"total"`
+	IsPublic  bool       `json:"isPublic"`
+	AutoRenew bool       `json:"autoRenew"`
+}
+
+// Attachment 附件信息（不含内容）
+type Attachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// Verification 入库时从正文提取的验证码和验证链接
+type Verification struct {
+	Code  string   `json:"code,omitempty"`  // 最可能的验证码
+	Codes []string `json:"codes,omitempty"` // 全部候选验证码
+	Links []string `json:"links,omitempty"` // 验证、激活、登录或重置密码链接
+}
+
+// Message 邮件（列表中的邮件可能没有正文，完整内容用 GetMessage 获取）
+type Message struct {
+	ID              string            `json:"id"`
+	MailboxID       string            `json:"mailboxId"`
+	Seq             int64             `json:"seq"`
+	From            string            `json:"from"`
+	To              string            `json:"to"`
+	Subject         string            `json:"subject"`
+	Text            string            `json:"text"`
+	HTML            string            `json:"html"`
+	IsRead          bool              `json:"isRead"`
+	IsSpam          bool              `json:"isSpam"`
+	Quarantined     bool              `json:"quarantined"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	ReceivedAt      time.Time         `json:"receivedAt"`
+	Attachments     []Attachment      `json:"attachments,omitempty"`
+	CapturedHeaders map[string]string `json:"capturedHeaders,omitempty"`
+	ExpiresAt       *time.Time        `json:"expiresAt,omitempty"`
+	Verification    *Verification     `json:"verification,omitempty"`
+}
+
+// MessageList 一页邮件
+type MessageList struct {
+	Items   []Message `json:"items"`
+	Count   int       `json:"count"`   // 本页数量
+	Total   int       `json:"total"`   // 符合条件的邮件总数
+	Offset  int       `json:"offset"`  // 本页起始位置
+	HasMore bool      `json:"hasMore"` // 之后是否还有邮件
+}
+
+// ListOptions 邮件列表参数
+type ListOptions struct {
+	UnreadOnly bool
+	Headers    map[string]string // 按收信时保存的头精确过滤，如 X-Test-Run-Id
+	Limit      int               // 默认 50，最多 200
+	Offset     int
+}
+
+// OTP 邮件中提取的验证码和验证链接
+type OTP struct {
+	MessageID  string    `json:"messageId"`
+	From       string    `json:"from"`
+	Subject    string    `json:"subject"`
+	ReceivedAt time.Time `json:"receivedAt"`
+	Verification
+}
+
+// CreateMailbox 创建邮箱
+func (c *Client) CreateMailbox(ctx context.Context, req CreateMailboxRequest) (*Mailbox, error) {
+	body := struct {
+		CreateMailboxRequest
+		ExpiresIn string `json:"expiresIn,omitempty"`
+	}{CreateMailboxRequest: req}
+	if req.ExpiresIn > 0 {
+		body.ExpiresIn = req.ExpiresIn.String()
+	}
+	var mailbox Mailbox
+	if err := c.do(ctx, http.MethodPost, "/v1/mailboxes", "", body, &mailbox); err != nil {
+		return nil, err
+	}
+	return &mailbox, nil
+}
+
+// GetMailbox 获取邮箱（含未读和邮件数）
+func (c *Client) GetMailbox(ctx context.Context, mailbox *Mailbox) (*Mailbox, error) {
+	var result Mailbox
+	if err := c.doMailbox(ctx, http.MethodGet, mailbox, "", nil, &result); err != nil {
+		return nil, err
+	}
+	if result.Token == "" {
+		result.Token = mailbox.Token
+	}
+	return &result, nil
+}
+
+// DeleteMailbox 删除邮箱及其邮件
+func (c *Client) DeleteMailbox(ctx context.Context, mailbox *Mailbox) error {
+	return c.doMailbox(ctx, http.MethodDelete, mailbox, "", nil, nil)
+}
+
+// ListMessages 分页列出邮件（不含隔离区），按接收时间倒序
+func (c *Client) ListMessages(ctx context.Context, mailbox *Mailbox, opts ListOptions) (*MessageList, error) {
+	query := url.Values{}
+	if opts.UnreadOnly {
+		query.Set("unreadOnly", "true")
+	}
+	for name, value := range opts.Headers {
+		query.Set("header."+name, value)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	path := "/messages"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var list MessageList
+	if err := c.doMailbox(ctx, http.MethodGet, mailbox, path, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetMessage 获取邮件的完整内容
+func (c *Client) GetMessage(ctx context.Context, mailbox *Mailbox, messageID string) (*Message, error) {
+	var message Message
+	if err := c.doMailbox(ctx, http.MethodGet, mailbox, "/messages/"+url.PathEscape(messageID), nil, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// DeleteMessage 删除邮件
+func (c *Client) DeleteMessage(ctx context.Context, mailbox *Mailbox, messageID string) error {
+	return c.doMailbox(ctx, http.MethodDelete, mailbox, "/messages/"+url.PathEscape(messageID), nil, nil)
+}
+
+// GetMessageRaw 获取入库时保存的原始邮件（RFC 822）
+func (c *Client) GetMessageRaw(ctx context.Context, mailbox *Mailbox, messageID string) ([]byte, error) {
+	var raw bytes.Buffer
+	if err := c.doMailbox(ctx, http.MethodGet, mailbox, "/messages/"+url.PathEscape(messageID)+"/raw", nil, &raw); err != nil {
+		return nil, err
+	}
+	return raw.Bytes(), nil
+}
+
+// LatestOTP 最新一封带验证码或验证链接的邮件（since 非零时只查找此后接收的邮件）
+//
+// 没有找到时返回 404 的 APIError，可用 IsNotFound 判断。
+func (c *Client) LatestOTP(ctx context.Context, mailbox *Mailbox, since time.Time) (*OTP, error) {
+	path := "/otp"
+	if !since.IsZero() {
+		path += "?since=" + url.QueryEscape(since.UTC().Format(time.RFC3339))
+	}
+	var otp OTP
+	if err := c.doMailbox(ctx, http.MethodGet, mailbox, path, nil, &otp); err != nil {
+		return nil, err
+	}
+	return &otp, nil
+}
+
+// WaitOptions 等待邮件的参数
+type WaitOptions struct {
+	Timeout  time.Duration // 最长等待时间，默认 DefaultWaitTimeout
+	Interval time.Duration // 轮询间隔，默认 DefaultPollInterval
+	// Since 只等待不早于此时接收的邮件（按服务端的接收时间比较），为零时已有的邮件也算
+	Since time.Time
+	// Headers 按收信时保存的头过滤，如 {"X-Test-Run-Id": runID}
+	Headers map[string]string
+	// Match 额外的过滤条件，参数为列表中的邮件（不一定有正文）
+	Match func(*Message) bool
+}
+
+// WaitForMessage 轮询等待符合条件的邮件，返回其中最早接收的一封的完整内容
+//
+// 超过 Timeout 时返回 ErrWaitTimeout；ctx 取消时返回 ctx 的错误。
+func (c *Client) WaitForMessage(ctx context.Context, mailbox *Mailbox, opts WaitOptions) (*Message, error) {
+	var found *Message
+	err := c.poll(ctx, opts, func(ctx context.Context) (bool, error) {
+		list, err := c.ListMessages(ctx, mailbox, ListOptions{Headers: opts.Headers, Limit: 200})
+		if err != nil {
+			return false, err
+		}
+		// 列表按接收时间倒序，从后往前找最早的一封
+		for i := len(list.Items) - 1; i >= 0; i-- {
+			message := &list.Items[i]
+			if !opts.Since.IsZero() && message.ReceivedAt.Before(opts.Since) {
+				continue
+			}
+			if opts.Match != nil && !opts.Match(message) {
+				continue
+			}
+			found, err = c.GetMessage(ctx, mailbox, message.ID)
+			return err == nil, err
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// WaitForOTP 轮询等待带验证码或验证链接的邮件（Since 和轮询参数同 WaitForMessage，忽略 Headers 和 Match）
+func (c *Client) WaitForOTP(ctx context.Context, mailbox *Mailbox, opts WaitOptions) (*OTP, error) {
+	var found *OTP
+	err := c.poll(ctx, opts, func(ctx context.Context) (bool, error) {
+		otp, err := c.LatestOTP(ctx, mailbox, opts.Since)
+		if IsNotFound(err) {
+			return false, nil
+		}
+		found = otp
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// poll 按间隔调用 check 直到返回 true、出错或超时
+func (c *Client) poll(ctx context.Context, opts WaitOptions, check func(ctx context.Context) (bool, error)) error {
+	timeout, interval := opts.Timeout, opts.Interval
+	if timeout <= 0 {
+		timeout = DefaultWaitTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		done, err := check(waitCtx)
+		if done {
+			return nil
+		}
+		if err != nil && waitCtx.Err() == nil {
+			return err
+		}
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return ErrWaitTimeout
+		case <-ticker.C:
+		}
+	}
+}
+
+// doMailbox 访问邮箱下的接口（path 为 /v1/mailboxes/{id} 之后的部分）
+func (c *Client) doMailbox(ctx context.Context, method string, mailbox *Mailbox, path string, body, out any) error {
+	if mailbox == nil || mailbox.ID == "" {
+		return errors.New("tempmail: mailbox ID required")
+	}
+	fullPath := "/v1/mailboxes/" + url.PathEscape(mailbox.ID) + path
+	if mailbox.Token != "" {
+		return c.do(ctx, method, fullPath, mailbox.Token, body, out)
+	}
+	return c.do(ctx, method, fullPath, "", body, out)
+}
+
+// do 发送请求并把响应的 data 解码到 out（out 为 *bytes.Buffer 时原样写入响应体）
+//
+// mailboxToken 为空时使用访问令牌或 API Key。
+func (c *Client) do(ctx context.Context, method, path, mailboxToken string, body, out any) error {
+	var reqBody io.Reader
+	var raw []byte
+	if body != nil {
+		var err error
+		raw, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(raw)), nil }
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case mailboxToken != "":
+		req.Header.Set("X-Mailbox-Token", mailboxToken)
+	case c.accessToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	case c.apiKey != "":
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeError(resp)
+	}
+	if buf, ok := out.(*bytes.Buffer); ok {
+		_, err := buf.ReadFrom(resp.Body)
+		return err
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	envelope := struct {
+		Data any `json:"data"`
+	}{Data: out}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("tempmail: decode response: %w", err)
+	}
+	return nil
+}
+
+// decodeError 解析错误响应：原生格式 {"code","msg","data"}，认证中间件为 {"error"}
+func decodeError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	var body struct {
+		Code  int    `json:"code"`
+		Msg   string `json:"msg"`
+		Error string `json:"error"`
+		Data  struct {
+			Reason string `json:"reason"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(raw, &body) == nil {
+		apiErr.Code = body.Code
+		apiErr.Message = body.Msg
+		if apiErr.Message == "" {
+			apiErr.Message = body.Error
+		}
+		apiErr.Reason = body.Data.Reason
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/internal/config"
+	"tempmail/backend/internal/service"
+	"tempmail/backend/internal/storage/memory"
+	httptransport "tempmail/backend/internal/transport/http"
+)
+
+// newAPIServer 以内存存储启动真实的 v1 路由
+func newAPIServer(t *testing.T) (*Client, *service.MessageService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore(time.Hour)
+	cfg := &config.Config{Mailbox: config.MailboxConfig{AllowedDomains: []string{"temp.example"}, DefaultTTL: time.Hour}}
+	cfg.CORS.AllowedOrigins = []string{"*"}
+	messages := service.NewMessageService(store)
+	server := httptest.NewServer(httptransport.NewRouter(httptransport.RouterDependencies{
+		Config:         cfg,
+		MailboxService: service.NewMailboxService(store, store, cfg),
+		MessageService: messages,
+		Store:          store,
+	}))
+	t.Cleanup(server.Close)
+	return New(server.URL + "/"), messages
+}
+
+func TestClient(t *testing.T) {
+	c, messages := newAPIServer(t)
+	ctx := t.Context()
+
+	mailbox, err := c.CreateMailbox(ctx, CreateMailboxRequest{Prefix: "ci-run", ExpiresIn: 30 * time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, "ci-run@temp.example", mailbox.Address)
+	assert.NotEmpty(t, mailbox.Token)
+	require.NotNil(t, mailbox.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), *mailbox.ExpiresAt, time.Minute)
+
+	deliver := func(subject, text string) {
+		_, err := messages.Create(context.Background(), service.CreateMessageInput{
+			MailboxID: mailbox.ID, From: "noreply@example.com", To: mailbox.Address, Subject: subject, Text: text,
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("错误响应", func(t *testing.T) {
+		_, err := c.CreateMailbox(ctx, CreateMailboxRequest{Prefix: "ci-run"})
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+		assert.Equal(t, "ADDRESS_TAKEN", apiErr.Reason)
+
+		_, err = c.ListMessages(ctx, &Mailbox{ID: mailbox.ID, Token: "wrong"}, ListOptions{})
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+		assert.Equal(t, "invalid mailbox token", apiErr.Message)
+
+		_, err = c.GetMessage(ctx, mailbox, "missing")
+		assert.True(t, IsNotFound(err))
+	})
+
+	t.Run("等待邮件", func(t *testing.T) {
+		_, err := c.WaitForMessage(ctx, mailbox, WaitOptions{Timeout: 50 * time.Millisecond, Interval: 10 * time.Millisecond})
+		assert.ErrorIs(t, err, ErrWaitTimeout)
+
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+			deliver("welcome", "hello")
+			deliver("verify", "Your verification code is 739201")
+		}()
+		message, err := c.WaitForMessage(ctx, mailbox, WaitOptions{
+			Timeout: 5 * time.Second, Interval: 10 * time.Millisecond,
+			Match: func(m *Message) bool { return m.Subject == "verify" },
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Your verification code is 739201", message.Text)
+
+		list, err := c.ListMessages(ctx, mailbox, ListOptions{Limit: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 2, list.Total)
+		assert.True(t, list.HasMore)
+
+		info, err := c.GetMailbox(ctx, &Mailbox{ID: mailbox.ID, Token: mailbox.Token})
+		require.NoError(t, err)
+		assert.Equal(t, 2, info.Total)
+	})
+
+	t.Run("等待验证码", func(t *testing.T) {
+		otp, err := c.WaitForOTP(ctx, mailbox, WaitOptions{Timeout: time.Second, Interval: 10 * time.Millisecond})
+		require.NoError(t, err)
+		assert.Equal(t, "739201", otp.Code)
+		assert.Equal(t, "verify", otp.Subject)
+
+		_, err = c.LatestOTP(ctx, mailbox, time.Now().Add(time.Hour))
+		assert.True(t, IsNotFound(err))
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = c.WaitForOTP(canceled, mailbox, WaitOptions{Since: time.Now().Add(time.Hour)})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("删除邮箱", func(t *testing.T) {
+		require.NoError(t, c.DeleteMailbox(ctx, mailbox))
+		_, err := c.GetMailbox(ctx, mailbox)
+		assert.True(t, IsNotFound(err))
+	})
+}
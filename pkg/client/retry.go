@@ -0,0 +1,173 @@
+// Package client tempmail v1 REST API 的 Go 客户端
+//
+// Client 封装测试中常用的接口：创建邮箱、列出和读取邮件、轮询等待邮件和验证码。数据结构与
+// API 响应（docs/swagger.yaml）一致，client_test.go 针对真实的路由运行，接口变化时测试会失败。
+//
+// RetryTransport 包装 http.RoundTripper：服务端以 429 或 503 拒绝时按 Retry-After（或响应体中的
+// retryAfterSeconds）等待后自动重试，没有提示时按指数退避；等待时间有上限，服务端要求的等待超过上限时
+// 直接返回原响应，交给调用方处理（如数据导出每小时一次的配额）。
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 默认参数
+const (
+	DefaultMaxRetries = 3
+	DefaultMaxWait    = time.Minute
+	DefaultBackoff    = time.Second
+	// maxHintBody 读取响应体中 retryAfterSeconds 的最大字节数
+	maxHintBody = 64 << 10
+)
+
+// RetryTransport 按服务端的重试提示自动重试的 http.RoundTripper
+type RetryTransport struct {
+	Base       http.RoundTripper // 为空时使用 http.DefaultTransport
+	MaxRetries int               // 最多重试次数
+	MaxWait    time.Duration     // 单次等待上限
+	Backoff    time.Duration     // 没有重试提示时的首次退避，之后每次翻倍
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryTransport 使用默认参数创建重试 Transport
+func NewRetryTransport(base http.RoundTripper) *RetryTransport {
+	return &RetryTransport{
+		Base:       base,
+		MaxRetries: DefaultMaxRetries,
+		MaxWait:    DefaultMaxWait,
+		Backoff:    DefaultBackoff,
+	}
+}
+
+// NewHTTPClient 创建自动重试的 http.Client
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: NewRetryTransport(nil), Timeout: timeout}
+}
+
+// SetClock 设置时间来源和等待函数（测试用）
+func (t *RetryTransport) SetClock(now func() time.Time, sleep func(ctx context.Context, d time.Duration) error) {
+	t.now = now
+	t.sleep = sleep
+}
+
+// RoundTrip 实现 http.RoundTripper
+//
+// 只有能重放请求体（无请求体或设置了 GetBody）的请求才会重试；ctx 取消时停止等待并返回 ctx 的错误。
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := base.RoundTrip(req)
+		if err != nil || !retryableStatus(resp.StatusCode) || !replayable || attempt >= t.MaxRetries {
+			return resp, err
+		}
+
+		wait, ok := RetryAfter(resp, t.clock())
+		if !ok {
+			wait = t.Backoff << attempt
+		}
+		if wait > t.MaxWait {
+			if ok {
+				return resp, nil // 服务端要求等待的时间超过上限，重试也只会再次被拒绝
+			}
+			wait = t.MaxWait
+		}
+
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxHintBody))
+		_ = resp.Body.Close()
+		if err := t.wait(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// RetryAfter 解析响应的重试间隔
+//
+// 优先使用 Retry-After 头（秒数或 HTTP 日期），没有时读取 JSON 响应体中的 retryAfterSeconds
+// （原生 API 在 data 下，兼容 API 在顶层）；读取过的响应体会被还原，调用方仍可正常读取。
+func RetryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return max(at.Sub(now), 0), true
+		}
+	}
+
+	if resp.Body == nil {
+		return 0, false
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxHintBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(raw), resp.Body), resp.Body}
+	if err != nil {
+		return 0, false
+	}
+
+	var hint struct {
+		RetryAfterSeconds *int `json:"retryAfterSeconds"`
+		Data              struct {
+			RetryAfterSeconds *int `json:"retryAfterSeconds"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(raw, &hint) != nil {
+		return 0, false
+	}
+	for _, seconds := range []*int{hint.Data.RetryAfterSeconds, hint.RetryAfterSeconds} {
+		if seconds != nil && *seconds >= 0 {
+			return time.Duration(*seconds) * time.Second, true
+		}
+	}
+	return 0, false
+}
+
+// retryableStatus 是否为可按提示重试的状态码（限流、暂时不可用）
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+func (t *RetryTransport) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+func (t *RetryTransport) wait(ctx context.Context, d time.Duration) error {
+	if t.sleep != nil {
+		return t.sleep(ctx, d)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock 记录等待时长而不真正等待
+type fakeClock struct {
+	now   time.Time
+	slept []time.Duration
+}
+
+func (f *fakeClock) Now() time.Time { return f.now }
+
+func (f *fakeClock) Sleep(_ context.Context, d time.Duration) error {
+	f.slept = append(f.slept, d)
+	f.now = f.now.Add(d)
+	return nil
+}
+
+// newScriptedServer 依次返回 responses 中的响应，用完后返回 200
+func newScriptedServer(t *testing.T, responses ...func(w http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		n := int(calls.Add(1))
+		if n <= len(responses) {
+			responses[n-1](w)
+			return
+		}
+		_, _ = w.Write(append([]byte("ok:"), body...))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newTestClient() (*http.Client, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	transport := NewRetryTransport(nil)
+	transport.SetClock(clock.Now, clock.Sleep)
+	return &http.Client{Transport: transport}, clock
+}
+
+func TestRetryTransport(t *testing.T) {
+	t.Run("按 Retry-After 等待后重试", func(t *testing.T) {
+		server, calls := newScriptedServer(t, func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		client, clock := newTestClient()
+
+		resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "ok:payload", string(body))
+		assert.Equal(t, int32(2), calls.Load())
+		assert.Equal(t, []time.Duration{7 * time.Second}, clock.slept)
+	})
+
+	t.Run("没有响应头时读取兼容 API 响应体中的提示", func(t *testing.T) {
+		server, _ := newScriptedServer(t, func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":"维护中","errorCode":"MAINTENANCE","retryAfterSeconds":3}`))
+		})
+		client, clock := newTestClient()
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []time.Duration{3 * time.Second}, clock.slept)
+	})
+
+	t.Run("HTTP 日期格式", func(t *testing.T) {
+		client, clock := newTestClient()
+		server, _ := newScriptedServer(t, func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", clock.now.Add(10*time.Second).Format(http.TimeFormat))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, []time.Duration{10 * time.Second}, clock.slept)
+	})
+
+	t.Run("没有提示时指数退避且不超过上限", func(t *testing.T) {
+		reject := func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) }
+		server, calls := newScriptedServer(t, reject, reject, reject, reject)
+		client, clock := newTestClient()
+		client.Transport.(*RetryTransport).MaxWait = 3 * time.Second
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, int32(DefaultMaxRetries+1), calls.Load())
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, clock.slept)
+	})
+
+	t.Run("要求等待超过上限时直接返回", func(t *testing.T) {
+		server, calls := newScriptedServer(t, func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"code":429,"data":{"errorCode":"QUOTA_EXCEEDED","retryAfterSeconds":3600}}`))
+		})
+		client, clock := newTestClient()
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, int32(1), calls.Load())
+		assert.Empty(t, clock.slept)
+
+		wait, ok := RetryAfter(resp, clock.now)
+		assert.True(t, ok)
+		assert.Equal(t, time.Hour, wait)
+	})
+}
+
+func TestRetryAfter_RestoresBody(t *testing.T) {
+	body := `{"code":429,"msg":"请求过于频繁","data":{"errorCode":"RATE_LIMITED","retryAfterSeconds":12}}`
+	resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}
+
+	wait, ok := RetryAfter(resp, time.Now())
+	assert.True(t, ok)
+	assert.Equal(t, 12*time.Second, wait)
+
+	restored, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(restored))
+}
@@ -0,0 +1,254 @@
+// tempmail gRPC API：程序化管理临时邮箱，供高并发的测试工具使用二进制传输和服务端流。
+//
+// 认证通过 metadata 传递：authorization: Bearer <邮箱令牌或用户访问令牌>（也可用 x-mailbox-token）。
+// 访问邮箱的方法要求邮箱令牌，或邮箱所有者及组织成员的访问令牌；CreateMailbox 带访问令牌时邮箱归属该用户。
+//
+// 修改后重新生成：make proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: tempmail.proto
+
+package tempmailpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TempMail_CreateMailbox_FullMethodName  = "/tempmail.v1.TempMail/CreateMailbox"
+	TempMail_ListMessages_FullMethodName   = "/tempmail.v1.TempMail/ListMessages"
+	TempMail_GetMessage_FullMethodName     = "/tempmail.v1.TempMail/GetMessage"
+	TempMail_StreamMessages_FullMethodName = "/tempmail.v1.TempMail/StreamMessages"
+)
+
+// TempMailClient is the client API for TempMail service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TempMailClient interface {
+	// CreateMailbox 创建临时邮箱，返回的 token 用于访问该邮箱
+	CreateMailbox(ctx context.Context, in *CreateMailboxRequest, opts ...grpc.CallOption) (*Mailbox, error)
+	// ListMessages 分页列出邮件（不含隔离区），按接收时间倒序
+	ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error)
+	// GetMessage 获取单封邮件的正文、附件列表和提取的验证码
+	GetMessage(ctx context.Context, in *GetMessageRequest, opts ...grpc.CallOption) (*Message, error)
+	// StreamMessages 推送新邮件：先补发最近的邮件（replayed），之后实时推送，直到客户端取消或邮箱失效
+	StreamMessages(ctx context.Context, in *StreamMessagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MessageEvent], error)
+}
+
+type tempMailClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTempMailClient(cc grpc.ClientConnInterface) TempMailClient {
+	return &tempMailClient{cc}
+}
+
+func (c *tempMailClient) CreateMailbox(ctx context.Context, in *CreateMailboxRequest, opts ...grpc.CallOption) (*Mailbox, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Mailbox)
+	err := c.cc.Invoke(ctx, TempMail_CreateMailbox_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tempMailClient) ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMessagesResponse)
+	err := c.cc.Invoke(ctx, TempMail_ListMessages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tempMailClient) GetMessage(ctx context.Context, in *GetMessageRequest, opts ...grpc.CallOption) (*Message, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Message)
+	err := c.cc.Invoke(ctx, TempMail_GetMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tempMailClient) StreamMessages(ctx context.Context, in *StreamMessagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MessageEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TempMail_ServiceDesc.Streams[0], TempMail_StreamMessages_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamMessagesRequest, MessageEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TempMail_StreamMessagesClient = grpc.ServerStreamingClient[MessageEvent]
+
+// TempMailServer is the server API for TempMail service.
+// All implementations must embed UnimplementedTempMailServer
+// for forward compatibility.
+type TempMailServer interface {
+	// CreateMailbox 创建临时邮箱，返回的 token 用于访问该邮箱
+	CreateMailbox(context.Context, *CreateMailboxRequest) (*Mailbox, error)
+	// ListMessages 分页列出邮件（不含隔离区），按接收时间倒序
+	ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error)
+	// GetMessage 获取单封邮件的正文、附件列表和提取的验证码
+	GetMessage(context.Context, *GetMessageRequest) (*Message, error)
+	// StreamMessages 推送新邮件：先补发最近的邮件（replayed），之后实时推送，直到客户端取消或邮箱失效
+	StreamMessages(*StreamMessagesRequest, grpc.ServerStreamingServer[MessageEvent]) error
+	mustEmbedUnimplementedTempMailServer()
+}
+
+// UnimplementedTempMailServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTempMailServer struct{}
+
+func (UnimplementedTempMailServer) CreateMailbox(context.Context, *CreateMailboxRequest) (*Mailbox, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateMailbox not implemented")
+}
+func (UnimplementedTempMailServer) ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMessages not implemented")
+}
+func (UnimplementedTempMailServer) GetMessage(context.Context, *GetMessageRequest) (*Message, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMessage not implemented")
+}
+func (UnimplementedTempMailServer) StreamMessages(*StreamMessagesRequest, grpc.ServerStreamingServer[MessageEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMessages not implemented")
+}
+func (UnimplementedTempMailServer) mustEmbedUnimplementedTempMailServer() {}
+func (UnimplementedTempMailServer) testEmbeddedByValue()                  {}
+
+// UnsafeTempMailServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TempMailServer will
+// result in compilation errors.
+type UnsafeTempMailServer interface {
+	mustEmbedUnimplementedTempMailServer()
+}
+
+func RegisterTempMailServer(s grpc.ServiceRegistrar, srv TempMailServer) {
+	// If the following call pancis, it indicates UnimplementedTempMailServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TempMail_ServiceDesc, srv)
+}
+
+func _TempMail_CreateMailbox_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateMailboxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TempMailServer).CreateMailbox(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TempMail_CreateMailbox_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TempMailServer).CreateMailbox(ctx, req.(*CreateMailboxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TempMail_ListMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TempMailServer).ListMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TempMail_ListMessages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TempMailServer).ListMessages(ctx, req.(*ListMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TempMail_GetMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TempMailServer).GetMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TempMail_GetMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TempMailServer).GetMessage(ctx, req.(*GetMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TempMail_StreamMessages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamMessagesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TempMailServer).StreamMessages(m, &grpc.GenericServerStream[StreamMessagesRequest, MessageEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TempMail_StreamMessagesServer = grpc.ServerStreamingServer[MessageEvent]
+
+// TempMail_ServiceDesc is the grpc.ServiceDesc for TempMail service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TempMail_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tempmail.v1.TempMail",
+	HandlerType: (*TempMailServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateMailbox",
+			Handler:    _TempMail_CreateMailbox_Handler,
+		},
+		{
+			MethodName: "ListMessages",
+			Handler:    _TempMail_ListMessages_Handler,
+		},
+		{
+			MethodName: "GetMessage",
+			Handler:    _TempMail_GetMessage_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMessages",
+			Handler:       _TempMail_StreamMessages_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "tempmail.proto",
+}
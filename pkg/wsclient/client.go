@@ -0,0 +1,366 @@
+// Package wsclient WebSocket 实时通知协议（pkg/wsproto）的 Go 客户端
+//
+// 客户端负责认证、心跳应答和断线重连：重连后按每个邮箱已收到的最大 seq 重新订阅，服务端只补发
+// 之后的新邮件。事件通过 Events 通道交付，不认识的消息类型照常交付（只有信封），不会断开连接。
+// 取消 Connect 传入的 ctx 即关闭连接，随后关闭事件通道。
+package wsclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"tempmail/backend/pkg/wsproto"
+)
+
+var (
+	ErrUnauthorized = errors.New("websocket authentication failed") // 服务端拒绝认证
+	ErrSessionEnded = errors.New("websocket session ended")         // 服务端以会话失效关闭码断开，见 CloseCode
+)
+
+// 默认参数
+const (
+	DefaultReconnectMin = 500 * time.Millisecond
+	DefaultReconnectMax = 30 * time.Second
+	// readTimeout 超过此时长没有收到任何数据（含服务端心跳）时视为断线并重连
+	readTimeout  = 90 * time.Second
+	writeTimeout = 10 * time.Second
+	eventBuffer  = 64
+)
+
+// Auth 连接凭据
+//
+// Token 为用户访问令牌或邮箱令牌；使用邮箱令牌时需同时提供 MailboxID。两者都只提供 MailboxID 时
+// 以匿名身份连接，只能订阅公开收件箱。
+type Auth struct {
+	Token     string
+	MailboxID string
+}
+
+// Event 服务端推送的事件
+//
+// Message 为原始信封；已知类型的数据解码到对应字段，其余类型（包括客户端不认识的新类型）只有信封。
+type Event struct {
+	wsproto.Message
+	NewMail        *wsproto.NewMailData        // new_mail
+	MailboxUpdate  *wsproto.MailboxUpdateData  // mailbox_update
+	MailboxDeleted *wsproto.MailboxDeletedData // mailbox_deleted
+}
+
+// Client WebSocket 客户端
+type Client struct {
+	url    string
+	dialer *websocket.Dialer
+	events chan Event
+
+	mu           sync.Mutex
+	conn         *websocket.Conn
+	writeMu      sync.Mutex       // gorilla 连接同一时间只允许一个写者
+	cursors      map[string]int64 // 已订阅邮箱 -> 已收到的最大 seq
+	version      int              // 服务端 hello 中的协议版本
+	reconnectMin time.Duration
+	reconnectMax time.Duration
+	closeCode    int
+	err          error
+}
+
+// Connect 建立连接，首次连接失败时直接返回错误；之后断线自动重连，直到 ctx 取消或会话失效
+func Connect(ctx context.Context, rawURL string, auth Auth) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid websocket url: %w", err)
+	}
+	q := u.Query()
+	if auth.Token != "" {
+		q.Set("token", auth.Token)
+	}
+	if auth.MailboxID != "" {
+		q.Set("mailboxId", auth.MailboxID)
+	}
+	u.RawQuery = q.Encode()
+
+	c := &Client{
+		url:          u.String(),
+		dialer:       websocket.DefaultDialer,
+		events:       make(chan Event, eventBuffer),
+		cursors:      make(map[string]int64),
+		reconnectMin: DefaultReconnectMin,
+		reconnectMax: DefaultReconnectMax,
+	}
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		if c.conn != nil {
+			_ = c.conn.Close()
+		}
+		c.mu.Unlock()
+	}()
+	go c.run(ctx, conn)
+	return c, nil
+}
+
+// SetReconnectBackoff 设置重连间隔：从 minDelay 开始逐次翻倍，不超过 maxDelay
+func (c *Client) SetReconnectBackoff(minDelay, maxDelay time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reconnectMin, c.reconnectMax = minDelay, maxDelay
+}
+
+// Events 事件通道，连接结束（ctx 取消或会话失效）后关闭
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Err 事件通道关闭的原因：ctx 取消时为 ctx.Err()，会话失效时为 ErrSessionEnded 或 ErrUnauthorized
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// CloseCode 服务端断开会话时的关闭码（见 wsproto.CloseAuthExpired 等），未被断开时为 0
+func (c *Client) CloseCode() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeCode
+}
+
+// ProtocolVersion 服务端 hello 中的协议版本（尚未收到时为 0）
+func (c *Client) ProtocolVersion() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// Subscribe 订阅邮箱，since 为恢复游标（只补发 seq 大于 since 的新邮件，0 表示补发服务端窗口内的全部事件）
+//
+// 订阅结果以 subscribed 或 error 事件返回。断线期间调用时只记录订阅，重连后发送。
+func (c *Client) Subscribe(mailboxID string, since int64) error {
+	c.mu.Lock()
+	if seq, ok := c.cursors[mailboxID]; !ok || since > seq {
+		c.cursors[mailboxID] = since
+	}
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return c.write(conn, subscribeMessage(mailboxID, since))
+}
+
+// Unsubscribe 取消订阅邮箱
+func (c *Client) Unsubscribe(mailboxID string) error {
+	c.mu.Lock()
+	delete(c.cursors, mailboxID)
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return c.write(conn, &wsproto.Message{Type: wsproto.MessageTypeUnsubscribe, MailboxID: mailboxID, Timestamp: time.Now()})
+}
+
+func subscribeMessage(mailboxID string, since int64) *wsproto.Message {
+	data, _ := json.Marshal(wsproto.SubscribeData{ProtocolVersion: wsproto.Version, SinceSeq: since})
+	return &wsproto.Message{Type: wsproto.MessageTypeSubscribe, MailboxID: mailboxID, Data: data, Timestamp: time.Now()}
+}
+
+// dial 建立连接并设置心跳处理
+func (c *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	conn, resp, err := c.dialer.DialContext(ctx, c.url, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return nil, ErrUnauthorized
+		}
+		return nil, err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPingHandler(func(data string) error {
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeTimeout))
+	})
+	return conn, nil
+}
+
+// write 发送一条消息
+func (c *Client) write(conn *websocket.Conn, msg *wsproto.Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return conn.WriteJSON(msg)
+}
+
+// run 读取消息，断线后重连，直到 ctx 取消或会话失效
+func (c *Client) run(ctx context.Context, conn *websocket.Conn) {
+	defer close(c.events)
+
+	for {
+		err := c.readLoop(ctx, conn)
+		_ = conn.Close()
+
+		c.mu.Lock()
+		c.conn = nil
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) && wsproto.IsTerminalClose(closeErr.Code) {
+			c.closeCode, c.err = closeErr.Code, ErrSessionEnded
+		}
+		done := c.err != nil
+		c.mu.Unlock()
+		if done || ctx.Err() != nil {
+			c.finish(ctx.Err())
+			return
+		}
+
+		if conn = c.reconnect(ctx); conn == nil {
+			c.finish(ctx.Err())
+			return
+		}
+	}
+}
+
+// finish 记录结束原因（已有原因时保留）
+func (c *Client) finish(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// reconnect 按退避间隔重连并恢复订阅，ctx 取消或认证被拒绝时返回 nil
+func (c *Client) reconnect(ctx context.Context) *websocket.Conn {
+	c.mu.Lock()
+	delay, maxDelay := c.reconnectMin, c.reconnectMax
+	c.mu.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		conn, err := c.dial(ctx)
+		if errors.Is(err, ErrUnauthorized) {
+			c.finish(err)
+			return nil
+		}
+		if err == nil {
+			c.mu.Lock()
+			if ctx.Err() != nil {
+				c.mu.Unlock()
+				_ = conn.Close()
+				return nil
+			}
+			c.conn = conn
+			cursors := make(map[string]int64, len(c.cursors))
+			for mailboxID, seq := range c.cursors {
+				cursors[mailboxID] = seq
+			}
+			c.mu.Unlock()
+
+			for mailboxID, seq := range cursors {
+				if err = c.write(conn, subscribeMessage(mailboxID, seq)); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				return conn
+			}
+			_ = conn.Close()
+		}
+
+		if delay *= 2; delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+// readLoop 读取并分发消息，直到连接出错
+func (c *Client) readLoop(ctx context.Context, conn *websocket.Conn) error {
+	for {
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		var msg wsproto.Message
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			continue // 无法解析的消息直接忽略
+		}
+
+		switch msg.Type {
+		case wsproto.MessageTypePing:
+			if err := c.write(conn, &wsproto.Message{Type: wsproto.MessageTypePong, Timestamp: time.Now()}); err != nil {
+				return err
+			}
+			continue
+		case wsproto.MessageTypePong:
+			continue
+		case wsproto.MessageTypeHello:
+			c.mu.Lock()
+			c.version = msg.ProtocolVersion
+			c.mu.Unlock()
+			continue
+		}
+
+		event := c.decode(msg)
+		select {
+		case c.events <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// decode 解码已知类型的数据并推进恢复游标
+func (c *Client) decode(msg wsproto.Message) Event {
+	event := Event{Message: msg}
+	switch msg.Type {
+	case wsproto.MessageTypeNewMail:
+		var data wsproto.NewMailData
+		if json.Unmarshal(msg.Data, &data) == nil {
+			event.NewMail = &data
+			c.mu.Lock()
+			if seq, ok := c.cursors[msg.MailboxID]; ok && data.Seq > seq {
+				c.cursors[msg.MailboxID] = data.Seq
+			}
+			c.mu.Unlock()
+		}
+	case wsproto.MessageTypeMailboxUpdate:
+		var data wsproto.MailboxUpdateData
+		if json.Unmarshal(msg.Data, &data) == nil {
+			event.MailboxUpdate = &data
+		}
+	case wsproto.MessageTypeMailboxDeleted:
+		var data wsproto.MailboxDeletedData
+		if json.Unmarshal(msg.Data, &data) == nil {
+			event.MailboxDeleted = &data
+		}
+		c.mu.Lock()
+		delete(c.cursors, msg.MailboxID)
+		c.mu.Unlock()
+	case wsproto.MessageTypeMailboxSuspended:
+		// 订阅已被服务端撤销，重连后不再恢复
+		c.mu.Lock()
+		delete(c.cursors, msg.MailboxID)
+		c.mu.Unlock()
+	}
+	return event
+}
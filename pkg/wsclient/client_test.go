@@ -0,0 +1,203 @@
+package wsclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tempmail/backend/pkg/wsproto"
+)
+
+// fakeServer 按脚本应答的 WebSocket 服务端，每个连接交给 handle 处理
+type fakeServer struct {
+	*httptest.Server
+	conns chan *websocket.Conn
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	s := &fakeServer{conns: make(chan *websocket.Conn, 4)}
+	upgrader := websocket.Upgrader{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		s.conns <- conn
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeServer) url() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+func (s *fakeServer) accept(t *testing.T) *websocket.Conn {
+	t.Helper()
+	select {
+	case conn := <-s.conns:
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("客户端没有连接")
+		return nil
+	}
+}
+
+// readClient 读取客户端发来的一条消息
+func readClient(t *testing.T, conn *websocket.Conn) wsproto.Message {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var msg wsproto.Message
+	require.NoError(t, conn.ReadJSON(&msg))
+	return msg
+}
+
+func nextEvent(t *testing.T, c *Client) Event {
+	t.Helper()
+	select {
+	case event, ok := <-c.Events():
+		require.True(t, ok, "事件通道已关闭: %v", c.Err())
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有收到事件")
+		return Event{}
+	}
+}
+
+func waitClosed(t *testing.T, c *Client) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-c.Events():
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("事件通道没有关闭")
+		}
+	}
+}
+
+func TestClient_ForwardCompatibility(t *testing.T) {
+	server := newFakeServer(t)
+	client, err := Connect(t.Context(), server.url(), Auth{Token: "tok", MailboxID: "mb-1"})
+	require.NoError(t, err)
+	conn := server.accept(t)
+
+	// 较新的服务端：信封和数据中都有 v1 客户端不认识的字段，还有不认识的消息类型
+	send := func(raw string) { require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(raw))) }
+	send(`{"type":"hello","data":{"protocolVersion":2,"minProtocolVersion":1,"features":["digest"]},"timestamp":"2024-05-01T12:00:00Z","protocolVersion":2,"region":"eu"}`)
+	require.NoError(t, client.Subscribe("mb-1", 0))
+	sub := readClient(t, conn)
+	assert.Equal(t, wsproto.MessageTypeSubscribe, sub.Type)
+	var req wsproto.SubscribeData
+	require.NoError(t, json.Unmarshal(sub.Data, &req))
+	assert.Equal(t, wsproto.Version, req.ProtocolVersion, "订阅时带上客户端实现的版本")
+
+	send(`{"type":"subscribed","mailboxId":"mb-1","timestamp":"2024-05-01T12:00:00Z","protocolVersion":1,"quota":{"left":3}}`)
+	send(`{"type":"mailbox_digest","mailboxId":"mb-1","data":{"count":3},"timestamp":"2024-05-01T12:00:00Z"}`)
+	send(`not json`)
+	send(`{"type":"ping","timestamp":"2024-05-01T12:00:00Z"}`)
+	send(`{"type":"new_mail","mailboxId":"mb-1","data":{"messageId":"msg-1","mailboxId":"mb-1","seq":7,"from":"a@example.com","to":"mb-1@temp.mail","subject":"Hi","hasHtml":false,"hasText":true,"createdAt":"2024-05-01T12:00:00Z","spamScore":0.1,"labels":["x"]},"timestamp":"2024-05-01T12:00:00Z","priority":"high"}`)
+
+	t.Run("心跳自动应答", func(t *testing.T) {
+		assert.Equal(t, wsproto.MessageTypePong, readClient(t, conn).Type)
+	})
+
+	t.Run("新增的可选字段不影响解码", func(t *testing.T) {
+		subscribed := nextEvent(t, client)
+		assert.Equal(t, wsproto.MessageTypeSubscribed, subscribed.Type)
+		assert.Equal(t, 1, subscribed.ProtocolVersion)
+		assert.Equal(t, 2, client.ProtocolVersion())
+	})
+
+	t.Run("不认识的消息类型只交付信封，连接保持", func(t *testing.T) {
+		unknown := nextEvent(t, client)
+		assert.Equal(t, wsproto.MessageType("mailbox_digest"), unknown.Type)
+		assert.Nil(t, unknown.NewMail)
+
+		mail := nextEvent(t, client)
+		require.NotNil(t, mail.NewMail)
+		assert.Equal(t, "msg-1", mail.NewMail.MessageID)
+		assert.Equal(t, int64(7), mail.NewMail.Seq)
+		assert.True(t, mail.NewMail.HasText)
+	})
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	server := newFakeServer(t)
+	client, err := Connect(t.Context(), server.url(), Auth{Token: "tok"})
+	require.NoError(t, err)
+	client.SetReconnectBackoff(10*time.Millisecond, 50*time.Millisecond)
+
+	conn := server.accept(t)
+	require.NoError(t, client.Subscribe("mb-1", 0))
+	require.NoError(t, client.Subscribe("mb-2", 3))
+	readClient(t, conn)
+	readClient(t, conn)
+	require.NoError(t, conn.WriteJSON(&wsproto.Message{
+		Type: wsproto.MessageTypeNewMail, MailboxID: "mb-1", Timestamp: time.Now(),
+		Data: json.RawMessage(`{"messageId":"msg-5","mailboxId":"mb-1","seq":5}`),
+	}))
+	assert.Equal(t, int64(5), nextEvent(t, client).NewMail.Seq)
+
+	// 服务端异常断开：客户端重连并按恢复游标重新订阅
+	require.NoError(t, conn.Close())
+	conn = server.accept(t)
+	cursors := map[string]int64{}
+	for range 2 {
+		msg := readClient(t, conn)
+		require.Equal(t, wsproto.MessageTypeSubscribe, msg.Type)
+		var req wsproto.SubscribeData
+		require.NoError(t, json.Unmarshal(msg.Data, &req))
+		cursors[msg.MailboxID] = req.SinceSeq
+	}
+	assert.Equal(t, map[string]int64{"mb-1": 5, "mb-2": 3}, cursors)
+
+	t.Run("会话失效的关闭码不再重连", func(t *testing.T) {
+		require.NoError(t, conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(wsproto.CloseTokenRevoked, "mailbox token revoked"), time.Now().Add(time.Second)))
+		waitClosed(t, client)
+		assert.ErrorIs(t, client.Err(), ErrSessionEnded)
+		assert.Equal(t, wsproto.CloseTokenRevoked, client.CloseCode())
+	})
+}
+
+func TestClient_Shutdown(t *testing.T) {
+	server := newFakeServer(t)
+
+	t.Run("认证失败时连接返回错误", func(t *testing.T) {
+		_, err := Connect(t.Context(), server.url(), Auth{Token: "wrong"})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("取消 ctx 关闭连接和事件通道", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		client, err := Connect(ctx, server.url(), Auth{Token: "tok"})
+		require.NoError(t, err)
+		conn := server.accept(t)
+
+		cancel()
+		waitClosed(t, client)
+		assert.ErrorIs(t, client.Err(), context.Canceled)
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		_, _, err = conn.ReadMessage()
+		assert.Error(t, err, "服务端看到连接关闭")
+	})
+}
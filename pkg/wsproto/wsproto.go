@@ -0,0 +1,168 @@
+// Package wsproto 定义 WebSocket 实时通知协议（/v1/ws）的线上格式
+//
+// 服务端 Hub 和外部客户端共用这里的类型，字段改名或删除会破坏已有集成，testdata 中的基准文件
+// 固定了每种消息的 JSON 编码。协议只做向后兼容的演进：新增字段一律可选，双方都忽略不认识的
+// 字段和消息类型；不兼容的变更必须提升 Version。
+//
+// 版本协商：连接建立后服务端先发送 hello（带 protocolVersion 和 minProtocolVersion），客户端在
+// subscribe 的 data 中带上期望的版本，服务端返回不高于该版本的最新版本（在 subscribed 的
+// protocolVersion 中回显），低于 MinVersion 时返回 unsupported_version 错误。
+package wsproto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 协议版本
+const (
+	Version    = 1 // 服务端实现的最新版本
+	MinVersion = 1 // 仍支持的最低版本
+)
+
+// Negotiate 按客户端请求的版本返回服务端使用的版本（0 表示未指定，使用最新版本）
+//
+// 请求的版本低于 MinVersion 时返回 false。
+func Negotiate(requested int) (int, bool) {
+	switch {
+	case requested <= 0 || requested >= Version:
+		return Version, true
+	case requested < MinVersion:
+		return 0, false
+	default:
+		return requested, true
+	}
+}
+
+// MessageType 消息类型
+type MessageType string
+
+// 服务端推送的消息类型
+const (
+	MessageTypeHello          MessageType = "hello"           // 连接建立，见 HelloData
+	MessageTypeNewMail        MessageType = "new_mail"        // 新邮件，见 NewMailData
+	MessageTypeMailboxUpdate  MessageType = "mailbox_update"  // 邮箱统计变化，见 MailboxUpdateData
+	MessageTypeMailboxDeleted MessageType = "mailbox_deleted" // 邮箱已删除，订阅随之撤销，见 MailboxDeletedData
+	MessageTypeSubscribed     MessageType = "subscribed"      // 订阅成功
+	MessageTypeError          MessageType = "error"           // 请求失败，见 Message.Code
+	MessageTypeDomainExpiring MessageType = "domain_expiring" // 用户域名即将到期（仅 JWT 连接）
+	MessageTypeMailboxesIdle  MessageType = "mailboxes_idle"  // 用户邮箱长期未访问（仅 JWT 连接）
+	// MessageTypePublicRevoked 公开收件箱被取消公开，匿名订阅已撤销
+	MessageTypePublicRevoked    MessageType = "public_access_revoked"
+	MessageTypeMailboxSuspended MessageType = "mailbox_suspended" // 邮箱被管理员停用，订阅已撤销
+	MessageTypeAuthExpiring     MessageType = "auth_expiring"     // 令牌即将过期，见 AuthExpiringData
+	MessageTypeReauthenticated  MessageType = "reauthenticated"   // 重新认证成功，见 ReauthenticatedData
+)
+
+// 客户端发送的消息类型
+const (
+	MessageTypeSubscribe   MessageType = "subscribe"   // 订阅邮箱，data 可选，见 SubscribeData
+	MessageTypeUnsubscribe MessageType = "unsubscribe" // 取消订阅
+	MessageTypeReauth      MessageType = "reauth"      // 重新认证，见 ReauthData
+)
+
+// 双向的心跳消息：服务端定期发送 ping，客户端回复 pong（连接层的 ping/pong 帧另行处理）
+const (
+	MessageTypePing MessageType = "ping"
+	MessageTypePong MessageType = "pong"
+)
+
+// Message 消息信封，所有消息都以此格式传输，具体内容在 Data 中
+type Message struct {
+	Type      MessageType     `json:"type"`
+	MailboxID string          `json:"mailboxId,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Error     string          `json:"error,omitempty"` // 错误描述（type=error）
+	Code      ErrorCode       `json:"code,omitempty"`  // 错误码（type=error）
+	Timestamp time.Time       `json:"timestamp"`
+	Replayed  bool            `json:"replayed,omitempty"` // 订阅时补发的历史事件
+	// ProtocolVersion 本连接使用的协议版本（hello、subscribed）
+	ProtocolVersion int `json:"protocolVersion,omitempty"`
+}
+
+// HelloData 连接建立后服务端发送的第一条消息
+type HelloData struct {
+	ProtocolVersion    int `json:"protocolVersion"`    // 服务端最新版本
+	MinProtocolVersion int `json:"minProtocolVersion"` // 服务端仍支持的最低版本
+}
+
+// SubscribeData 订阅参数（均可选）
+type SubscribeData struct {
+	ProtocolVersion int `json:"protocolVersion,omitempty"` // 客户端期望的版本，0 表示最新
+	// SinceSeq 恢复游标：只补发序号大于此值的新邮件（重连时传入已收到的最大 seq）
+	SinceSeq int64 `json:"sinceSeq,omitempty"`
+}
+
+// NewMailData 新邮件通知数据
+type NewMailData struct {
+	MessageID string `json:"messageId"`
+	MailboxID string `json:"mailboxId"`
+	Seq       int64  `json:"seq"` // 邮箱内入库序号，客户端据此排序和检测遗漏
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Subject   string `json:"subject"`
+	Preview   string `json:"preview,omitempty"`
+	HasHTML   bool   `json:"hasHtml"`
+	HasText   bool   `json:"hasText"`
+	CreatedAt string `json:"createdAt"` // RFC 3339
+}
+
+// MailboxUpdateData 邮箱更新通知数据
+type MailboxUpdateData struct {
+	MailboxID    string `json:"mailboxId"`
+	UnreadCount  int    `json:"unreadCount"`
+	TotalCount   int    `json:"totalCount"`
+	LastActivity string `json:"lastActivity"` // RFC 3339
+}
+
+// MailboxDeletedData 邮箱删除通知数据
+type MailboxDeletedData struct {
+	MailboxID string `json:"mailboxId"`
+	DeletedAt string `json:"deletedAt"` // RFC 3339
+}
+
+// AuthExpiringData 令牌即将过期通知数据
+type AuthExpiringData struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+	Deadline  time.Time `json:"deadline"` // 最晚重新认证时间，之后断开连接
+}
+
+// ReauthData 重新认证请求数据
+type ReauthData struct {
+	Token string `json:"token"`
+}
+
+// ReauthenticatedData 重新认证成功数据
+type ReauthenticatedData struct {
+	ExpiresAt  time.Time `json:"expiresAt"`
+	MailboxIDs []string  `json:"mailboxIds"` // 重新计算后可访问的邮箱
+}
+
+// ErrorCode 错误码（type=error 时的 code 字段）
+type ErrorCode string
+
+const (
+	ErrCodeInvalidRequest     ErrorCode = "invalid_request"     // 请求缺少必要字段或格式错误
+	ErrCodeForbidden          ErrorCode = "forbidden"           // 无权访问邮箱
+	ErrCodeUnsupportedVersion ErrorCode = "unsupported_version" // 请求的协议版本不再支持
+	ErrCodeOverflow           ErrorCode = "overflow"            // 客户端读取过慢，发送队列溢出，随后以 CloseSlowConsumer 断开
+)
+
+// 关闭码（4000-4999 为应用自定义范围），服务端以这些关闭码断开的连接不应自动重连
+const (
+	CloseAuthExpired      = 4001 // 令牌过期且未在宽限期内重新认证
+	CloseReauthFailed     = 4002 // 重新认证失败
+	CloseTokenRevoked     = 4003 // 邮箱令牌已失效
+	CloseUserDisabled     = 4004 // 用户被停用或删除
+	CloseMailboxSuspended = 4005 // 邮箱被管理员停用（凭邮箱令牌建立的连接）
+)
+
+// IsTerminalClose 关闭码是否表示会话已失效（重连也无法恢复）
+func IsTerminalClose(code int) bool {
+	return code >= CloseAuthExpired && code <= CloseMailboxSuspended
+}
+
+// CloseSlowConsumer 客户端读取过慢被断开（丢弃的事件过多或发送队列持续阻塞）
+//
+// 与上面的关闭码不同，会话本身仍然有效：客户端应立即重连，并带上已收到的最大 seq 重新订阅以补齐丢失的事件。
+const CloseSlowConsumer = 4006
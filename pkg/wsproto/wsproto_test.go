@@ -0,0 +1,154 @@
+package wsproto
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "用当前编码覆盖 testdata 中的基准文件")
+
+var sampleTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func rawJSON(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
+
+// goldenCases 每种线上消息的样例，基准文件固定其 JSON 编码
+func goldenCases(t *testing.T) map[string]interface{} {
+	return map[string]interface{}{
+		"hello": &Message{
+			Type:            MessageTypeHello,
+			Data:            rawJSON(t, HelloData{ProtocolVersion: 1, MinProtocolVersion: 1}),
+			Timestamp:       sampleTime,
+			ProtocolVersion: 1,
+		},
+		"subscribe": &Message{
+			Type:      MessageTypeSubscribe,
+			MailboxID: "mb-1",
+			Data:      rawJSON(t, SubscribeData{ProtocolVersion: 1, SinceSeq: 41}),
+			Timestamp: sampleTime,
+		},
+		"subscribed": &Message{
+			Type:            MessageTypeSubscribed,
+			MailboxID:       "mb-1",
+			Timestamp:       sampleTime,
+			ProtocolVersion: 1,
+		},
+		"new_mail": &Message{
+			Type:      MessageTypeNewMail,
+			MailboxID: "mb-1",
+			Data: rawJSON(t, NewMailData{
+				MessageID: "msg-1", MailboxID: "mb-1", Seq: 42,
+				From: "no-reply@example.com", To: "box@temp.mail", Subject: "Your code",
+				Preview: "123456", HasHTML: true, HasText: true, CreatedAt: "2024-05-01T12:00:00Z",
+			}),
+			Timestamp: sampleTime,
+			Replayed:  true,
+		},
+		"mailbox_update": &Message{
+			Type:      MessageTypeMailboxUpdate,
+			MailboxID: "mb-1",
+			Data:      rawJSON(t, MailboxUpdateData{MailboxID: "mb-1", UnreadCount: 2, TotalCount: 5, LastActivity: "2024-05-01T12:00:00Z"}),
+			Timestamp: sampleTime,
+		},
+		"mailbox_deleted": &Message{
+			Type:      MessageTypeMailboxDeleted,
+			MailboxID: "mb-1",
+			Data:      rawJSON(t, MailboxDeletedData{MailboxID: "mb-1", DeletedAt: "2024-05-01T12:00:00Z"}),
+			Timestamp: sampleTime,
+		},
+		"error": &Message{
+			Type:      MessageTypeError,
+			Code:      ErrCodeForbidden,
+			Error:     "no permission to access mailbox: mb-2",
+			Timestamp: sampleTime,
+		},
+		"auth_expiring": &Message{
+			Type:      MessageTypeAuthExpiring,
+			Data:      rawJSON(t, AuthExpiringData{ExpiresAt: sampleTime, Deadline: sampleTime.Add(time.Minute)}),
+			Timestamp: sampleTime,
+		},
+		"reauth": &Message{
+			Type:      MessageTypeReauth,
+			Data:      rawJSON(t, ReauthData{Token: "access-token"}),
+			Timestamp: sampleTime,
+		},
+		"reauthenticated": &Message{
+			Type:      MessageTypeReauthenticated,
+			Data:      rawJSON(t, ReauthenticatedData{ExpiresAt: sampleTime, MailboxIDs: []string{"mb-1", "mb-2"}}),
+			Timestamp: sampleTime,
+		},
+		"mailbox_suspended": &Message{
+			Type:      MessageTypeMailboxSuspended,
+			MailboxID: "mb-1",
+			Timestamp: sampleTime,
+		},
+		"ping": &Message{Type: MessageTypePing, Timestamp: sampleTime},
+	}
+}
+
+func TestGoldenEncodings(t *testing.T) {
+	for name, msg := range goldenCases(t) {
+		t.Run(name, func(t *testing.T) {
+			got, err := json.MarshalIndent(msg, "", "  ")
+			require.NoError(t, err)
+			got = append(got, '\n')
+
+			path := filepath.Join("testdata", name+".json")
+			if *update {
+				require.NoError(t, os.WriteFile(path, got, 0o644))
+				return
+			}
+			want, err := os.ReadFile(path)
+			require.NoError(t, err, "缺少基准文件，使用 -update 生成")
+			assert.Equal(t, string(want), string(got), "线上编码发生变化：字段改名会破坏已有客户端")
+
+			// 基准文件能解码回相同的消息
+			decoded := reflect.New(reflect.TypeOf(msg).Elem()).Interface()
+			require.NoError(t, json.Unmarshal(want, decoded))
+			reencoded, err := json.MarshalIndent(decoded, "", "  ")
+			require.NoError(t, err)
+			assert.Equal(t, string(want), string(reencoded)+"\n")
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	t.Run("未指定时使用最新版本", func(t *testing.T) {
+		version, ok := Negotiate(0)
+		assert.True(t, ok)
+		assert.Equal(t, Version, version)
+	})
+
+	t.Run("客户端版本更高时使用服务端最新版本", func(t *testing.T) {
+		version, ok := Negotiate(Version + 3)
+		assert.True(t, ok)
+		assert.Equal(t, Version, version)
+	})
+
+	t.Run("支持范围内返回请求的版本", func(t *testing.T) {
+		version, ok := Negotiate(MinVersion)
+		assert.True(t, ok)
+		assert.Equal(t, MinVersion, version)
+	})
+}
+
+func TestIsTerminalClose(t *testing.T) {
+	assert.True(t, IsTerminalClose(CloseAuthExpired))
+	assert.True(t, IsTerminalClose(CloseUserDisabled))
+	assert.True(t, IsTerminalClose(CloseMailboxSuspended))
+	assert.False(t, IsTerminalClose(CloseSlowConsumer), "读取过慢被断开后应重连")
+	assert.False(t, IsTerminalClose(1000))
+	assert.False(t, IsTerminalClose(1006))
+}